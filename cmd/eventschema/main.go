@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"cloudpan/internal/events"
+)
+
+func main() {
+	// 定义命令行参数
+	var (
+		out       = flag.String("out", "", "Directory to write <type>.v<version>.json files (empty prints to stdout)")
+		eventType = flag.String("type", "", "Only export this event type")
+		version   = flag.Int("version", 0, "Only export this version (0 means all versions)")
+	)
+	flag.Parse()
+
+	if *out != "" {
+		if err := os.MkdirAll(*out, 0o755); err != nil {
+			log.Fatalf("Failed to create output directory: %v", err)
+		}
+	}
+
+	exported := 0
+	for _, definition := range events.Definitions() {
+		if *eventType != "" && definition.Type != *eventType {
+			continue
+		}
+		if *version != 0 && definition.Version != *version {
+			continue
+		}
+
+		body, err := events.MarshalSchema(definition.Type, definition.Version)
+		if err != nil {
+			log.Fatalf("Failed to generate schema: %v", err)
+		}
+		body = append(body, '\n')
+		exported++
+
+		if *out == "" {
+			fmt.Print(string(body))
+			continue
+		}
+		path := filepath.Join(*out, fmt.Sprintf("%s.v%d.json", definition.Type, definition.Version))
+		if err := os.WriteFile(path, body, 0o644); err != nil {
+			log.Fatalf("Failed to write %s: %v", path, err)
+		}
+		log.Printf("Wrote %s", path)
+	}
+
+	if exported == 0 {
+		log.Fatalf("No event matches type=%q version=%d", *eventType, *version)
+	}
+}
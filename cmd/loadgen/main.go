@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"cloudpan/internal/pkg/loadgen"
+)
+
+func main() {
+	// 定义命令行参数
+	var (
+		target     = flag.String("target", "http://localhost:8080", "Target environment base URL")
+		users      = flag.Int("users", 10, "Number of concurrent virtual users")
+		duration   = flag.Duration("duration", time.Minute, "Test duration (0 means run until iterations are done)")
+		iterations = flag.Int("iterations", 0, "Max iterations per user (0 means unlimited)")
+		rampUp     = flag.Duration("ramp-up", 0, "Time over which to start all users")
+		mix        = flag.String("mix", "upload=1,list=5,search=2", "Scenario weights: upload, list, search")
+		token      = flag.String("token", os.Getenv("LOADGEN_TOKEN"), "Access token (defaults to $LOADGEN_TOKEN)")
+		identifier = flag.String("identifier", "", "Login identifier used when no token is given")
+		password   = flag.String("password", os.Getenv("LOADGEN_PASSWORD"), "Login password (defaults to $LOADGEN_PASSWORD)")
+		chunkSize  = flag.Int("chunk-size", 1024*1024, "Upload chunk size in bytes")
+		chunks     = flag.Int("chunks", 4, "Chunks per uploaded file")
+		parentID   = flag.Uint("parent-id", 0, "Folder ID used for uploads and listings (0 means root)")
+		queries    = flag.String("queries", "report,photo,backup", "Comma-separated search keywords")
+		timeout    = flag.Duration("timeout", 30*time.Second, "Per-request timeout")
+		jsonOutput = flag.Bool("json", false, "Print the report as JSON")
+	)
+	flag.Parse()
+
+	scenarioMix, err := loadgen.ParseMix(*mix)
+	if err != nil {
+		log.Fatalf("Invalid mix: %v", err)
+	}
+
+	runner, err := loadgen.NewRunner(loadgen.Config{
+		Target:     *target,
+		Users:      *users,
+		Duration:   *duration,
+		Iterations: *iterations,
+		RampUp:     *rampUp,
+		Mix:        scenarioMix,
+		Token:      *token,
+		Identifier: *identifier,
+		Password:   *password,
+		ChunkSize:  *chunkSize,
+		Chunks:     *chunks,
+		ParentID:   *parentID,
+		Queries:    splitList(*queries),
+		Timeout:    *timeout,
+	}, nil)
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+
+	// Ctrl+C 提前结束压测，仍然输出已收集的结果
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Fprintf(os.Stderr, "Running load test against %s with %d users...\n", *target, *users)
+	report, runErr := runner.Run(ctx)
+
+	if *jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			log.Fatalf("Failed to write report: %v", err)
+		}
+	} else if err := report.WriteText(os.Stdout); err != nil {
+		log.Fatalf("Failed to write report: %v", err)
+	}
+
+	if runErr != nil {
+		log.Fatalf("Load test failed: %v", runErr)
+	}
+}
+
+// splitList 拆分逗号分隔的参数
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
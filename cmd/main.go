@@ -15,16 +15,54 @@ import (
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 
 	"cloudpan/internal/api/routes"
+	"cloudpan/internal/pkg/backup"
+	"cloudpan/internal/pkg/buildinfo"
+	"cloudpan/internal/pkg/cache"
 	"cloudpan/internal/pkg/config"
 	"cloudpan/internal/pkg/database"
+	"cloudpan/internal/pkg/email"
+	"cloudpan/internal/pkg/indexing"
+	"cloudpan/internal/pkg/logger"
+	"cloudpan/internal/pkg/sms"
+	"cloudpan/internal/pkg/storage"
+	"cloudpan/internal/pkg/tracing"
+	"cloudpan/internal/pkg/utils"
+	filerepo "cloudpan/internal/repository/file"
+	systemrepo "cloudpan/internal/repository/system"
+	userrepo "cloudpan/internal/repository/user"
+	auditsvc "cloudpan/internal/service/audit"
+	featureflagsvc "cloudpan/internal/service/featureflag"
+	filesvc "cloudpan/internal/service/file"
+	"cloudpan/internal/service/jobs"
+	"cloudpan/internal/service/maintenance"
+	"cloudpan/internal/service/readonly"
+	"cloudpan/internal/service/sso"
+	usagesvc "cloudpan/internal/service/usage"
+	"cloudpan/internal/service/user"
+	"cloudpan/internal/service/verification"
+	"cloudpan/internal/service/warmup"
 )
 
+// referenceWarmupTimeout 启动预热的最长等待时间，超时后未完成的数据源在首次读取时加载
+const referenceWarmupTimeout = 10 * time.Second
+
+// defaultArchiveScanInterval 未配置归档扫描间隔时使用的默认值
+const defaultArchiveScanInterval = 6 * time.Hour
+
+// chunkCleanupInterval 过期上传分片的清理间隔
+const chunkCleanupInterval = time.Hour
+
+// defaultAPIUsageFlushInterval 未配置API用量写入间隔时使用的默认值
+const defaultAPIUsageFlushInterval = time.Minute
+
 func main() {
-	fmt.Println("HXLOS Cloud Storage - 启动中...")
+	printStartupBanner()
 
 	// 1. 加载配置文件
 	log.Println("Loading configuration...")
@@ -33,6 +71,23 @@ func main() {
 	}
 	log.Println("Configuration loaded successfully")
 
+	// 初始化应用日志和访问日志
+	if err := initLogger(); err != nil {
+		log.Fatalf("Failed to initialize logger: %v", err)
+	}
+	// 之后的启动信息和第三方库通过标准库log输出的日志写入应用日志，使用相同的格式、输出和轮转
+	restoreStdLog := logger.RedirectStdLog()
+	defer restoreStdLog()
+
+	// 分布式追踪，需在初始化数据库和Redis之前启用，以便注册GORM插件和Redis钩子
+	shutdownTracing := initTracing()
+
+	// 启用用户名和团队名称敏感词过滤
+	if profanity := config.AppConfig.Security.Profanity; profanity.Enabled {
+		utils.SetProfanityFilter(utils.NewProfanityFilter(profanity.Words))
+		log.Printf("Profanity filter enabled with %d words", len(profanity.Words))
+	}
+
 	// 2. 初始化数据库连接池
 	log.Println("Initializing database connections...")
 	if err := database.Init(); err != nil {
@@ -40,13 +95,98 @@ func main() {
 	}
 	log.Println("Database connections initialized successfully")
 
+	// 启动索引文档分发器，模型变更在事务提交后发布到搜索/缓存等消费者
+	indexDispatcher := indexing.NewDispatcher(indexing.DefaultQueueSize, logger.Logger)
+	indexing.SetPublisher(indexDispatcher)
+	go indexDispatcher.Run(context.Background())
+
+	// 连接Redis，令牌存储、缓存失效总线和维护任务锁在多实例间共享；不可用时改用进程内存储
+	initRedis()
+
+	// 启动跨实例缓存失效总线，其他实例修改数据后通知本实例清除进程内缓存
+	invalidationCtx, stopInvalidationBus := context.WithCancel(context.Background())
+	startInvalidationBus(invalidationCtx)
+
+	// 令牌吊销存储，管理员强制重置密码等场景下使用户已签发的令牌立即失效
+	initTokenStore()
+
+	// 管理员操作审计日志，需在设置路由前创建以便管理接口写入审计记录
+	auditsvc.SetDefault(auditsvc.NewAdminAuditService(systemrepo.NewAdminAuditRepository(database.GetDB()), logger.Logger))
+
+	// 安全审计日志(登录、密码、分享、权限变更和删除)，需在设置路由前创建以便处理器写入记录
+	auditsvc.SetDefaultSecurity(auditsvc.NewSecurityAuditService(systemrepo.NewAuditLogRepository(database.GetDB()), logger.Logger))
+
+	// 两步验证(TOTP)，需在设置路由前创建以便登录时检查
+	initTwoFactor()
+
+	// 短信验证码渠道，需在设置路由前创建；未启用时不提供短信登录和短信两步验证
+	initSMS()
+
+	// 企业单点登录，未启用时登录页不提供SSO
+	initSSO()
+
+	// 企业目录(LDAP)认证，未启用时密码登录只使用本地账户
+	initLDAP()
+
+	// 上传存储空间记账，上传开始前预留配额，需在启动分片清理和设置路由前创建
+	initStorageQuota()
+
+	// 只读模式，数据库维护或迁移期间拒绝修改数据的请求，需在设置路由前创建以注册只读中间件
+	initReadOnlyMode()
+
+	// 文件搜索历史和剪贴板，Redis未初始化时保存在进程内
+	initSearchHistoryStore()
+	initClipboardStore()
+
+	// 邮件发送队列，重试耗尽的邮件进入死信队列，需在设置路由前启动以注册死信管理接口
+	emailCtx, stopEmailContext := context.WithCancel(context.Background())
+	startEmailService(emailCtx)
+
+	// 预热套餐、策略、特性开关等热点参考数据，避免部署后首批请求集中回源数据库
+	warmReferenceData()
+
+	// 定期将长期未访问的文件转为归档存储
+	archiveCtx, stopArchiveTransitions := context.WithCancel(context.Background())
+	startArchiveTransitions(archiveCtx)
+
+	// 完成或清理上次崩溃时本地存储未完成的写入，需在开始处理上传请求前执行
+	recoverLocalStorage()
+
+	// 存储后端健康检查和读故障切换，需在创建使用存储的服务和设置路由前启用
+	storageHealthCtx, stopStorageHealth := context.WithCancel(context.Background())
+	startStorageHealth(storageHealthCtx)
+
+	// 后台任务队列，缩略图、转码等媒体任务按类型限制并发，需在设置路由前创建以注册管理接口
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	jobQueue := startJobQueue(jobsCtx)
+
+	// 按用户统计API用量，需在设置路由前启用以注册统计中间件
+	stopAPIUsage := startAPIUsage()
+
+	// 定期清理过期验证码、会话、分享和进程内计数，需在设置路由前创建以便注册限流器清理
+	maintenanceCtx, stopMaintenance := context.WithCancel(context.Background())
+	scheduler := newMaintenanceScheduler()
+
+	// 分片上传有效期按客户端调整，接收上传和清理过期上传的服务共享统计，需在设置路由前创建
+	filesvc.SetDefaultUploadTTLTuner(filesvc.NewUploadTTLTuner(filesvc.UploadTTLOptionsFromConfig(config.AppConfig.Storage.Upload.TTL)))
+
+	// 定期清理过期未合并的上传分片，启用维护调度器时作为维护任务执行
+	chunkCleanupCtx, stopChunkCleanup := context.WithCancel(context.Background())
+	startChunkCleanup(chunkCleanupCtx, scheduler)
+
 	// 3. 设置Gin模式
 	if !config.AppConfig.App.Debug {
 		gin.SetMode(gin.ReleaseMode)
 	}
+	// Gin的调试输出(路由注册、模式提示)和内部错误写入应用日志
+	gin.DefaultWriter = logger.StdLogger(zapcore.DebugLevel).Writer()
+	gin.DefaultErrorWriter = logger.StdLogger(zapcore.ErrorLevel).Writer()
 
 	// 4. 设置路由
 	r := routes.SetupRouter()
+	if scheduler != nil {
+		scheduler.Start(maintenanceCtx)
+	}
 
 	// 5. 创建HTTP服务器
 	srv := &http.Server{
@@ -82,12 +222,48 @@ func main() {
 		log.Printf("Server forced to shutdown: %v", err)
 	}
 
-	// 9. 关闭数据库连接
+	// 9. 停止接收缓存失效消息、归档扫描、分片清理和维护任务，写入剩余的API用量计数
+	stopInvalidationBus()
+	stopStorageHealth()
+	stopArchiveTransitions()
+	stopChunkCleanup()
+	stopMaintenance()
+	if scheduler != nil {
+		scheduler.Wait()
+	}
+	stopJobs()
+	jobQueue.Wait()
+	stopAPIUsage(ctx)
+	stopEmailContext()
+	if err := email.StopGlobalEmailService(); err != nil {
+		log.Printf("Failed to stop email service: %v", err)
+	}
+
+	// 10. 停止索引分发器，处理完已发布的文档
+	indexing.SetPublisher(nil)
+	indexDispatcher.Close()
+	select {
+	case <-indexDispatcher.Done():
+	case <-ctx.Done():
+		log.Println("Index dispatcher did not drain before shutdown timeout")
+	}
+
+	// 11. 关闭数据库和Redis连接
 	if err := database.Shutdown(); err != nil {
 		log.Printf("Failed to shutdown database: %v", err)
 	}
+	if err := cache.CloseRedis(); err != nil {
+		log.Printf("Failed to close Redis: %v", err)
+	}
+
+	// 12. 导出剩余的追踪数据
+	if err := shutdownTracing(ctx); err != nil {
+		log.Printf("Failed to shutdown tracing: %v", err)
+	}
 
 	log.Println("Server exited")
+	_ = logger.Sync()
+	_ = logger.SyncAccessLogger()
 
 	// 确保依赖被保留（防止go mod tidy移除）
 	_ = sql.Drivers
@@ -97,3 +273,691 @@ func main() {
 	_ = jwt.SigningMethodHS256
 	_ = context.TODO
 }
+
+// initLogger 按日志配置初始化应用日志和访问日志
+func initLogger() error {
+	logConfig := config.AppConfig.Log
+	return logger.InitializeLoggerSystem(logger.InitConfig{
+		AppLog: logger.LogConfig{
+			Level:      logConfig.Level,
+			Format:     logConfig.Format,
+			Output:     logConfig.Output,
+			FilePath:   logConfig.FilePath,
+			MaxSize:    logConfig.MaxSize,
+			MaxAge:     logConfig.MaxAge,
+			MaxBackups: logConfig.MaxBackups,
+			Compress:   logConfig.Compress,
+		},
+		AccessLog: logger.AccessLogConfig{
+			Enabled:  logConfig.AccessLog.Enabled,
+			FilePath: logConfig.AccessLog.FilePath,
+			Format:   logConfig.AccessLog.Format,
+		},
+	})
+}
+
+// initTracing 按配置启用分布式追踪，返回退出前导出剩余span的关闭函数
+//
+// 接收端不可用时只记录日志并关闭追踪，不影响服务启动
+func initTracing() func(context.Context) error {
+	info := buildinfo.Get()
+	shutdown, err := tracing.Init(context.Background(), config.AppConfig.Monitoring.Tracing, tracing.ServiceInfo{
+		Name:        config.AppConfig.App.Name,
+		Version:     info.Version,
+		Environment: config.AppConfig.App.Env,
+	})
+	if err != nil {
+		log.Printf("Tracing disabled: %v", err)
+		return func(context.Context) error { return nil }
+	}
+	if tracing.Enabled() {
+		cfg := config.AppConfig.Monitoring.Tracing
+		log.Printf("Tracing enabled: exporter=%s protocol=%s endpoint=%s sample_ratio=%v", cfg.Exporter, cfg.Protocol, cfg.Endpoint, cfg.SampleRatio)
+	}
+	return shutdown
+}
+
+// initRedis 连接Redis，未配置或连接失败时记录警告并继续启动
+//
+// 之后创建的令牌存储、缓存失效总线和维护任务锁按 cache.RedisClient 是否可用选择实现，
+// 多实例部署必须配置Redis，否则令牌吊销、缓存失效和维护任务互斥只在单个实例内生效
+func initRedis() {
+	if config.AppConfig.Redis.Host == "" {
+		log.Println("WARNING: Redis not configured, using in-process stores (single instance only)")
+		return
+	}
+	if err := cache.InitRedis(); err != nil {
+		log.Printf("WARNING: Redis unavailable, using in-process stores (single instance only): %v", err)
+	}
+}
+
+// startInvalidationBus 创建全局缓存失效总线并开始接收其他实例的消息
+//
+// Redis未初始化时总线只在本实例内分发，单实例部署不受影响
+func startInvalidationBus(ctx context.Context) {
+	bus := cache.NewInvalidationBus(cache.NewDefaultInvalidationTransport(), logger.Logger)
+	cache.SetDefaultInvalidationBus(bus)
+	go func() {
+		if err := bus.Run(ctx); err != nil {
+			log.Printf("Cache invalidation bus stopped: %v", err)
+		}
+	}()
+	log.Printf("Cache invalidation bus started: instance=%s, cross-instance=%v", bus.InstanceID(), bus.CrossInstance())
+}
+
+// printStartupBanner 输出启动横幅和构建信息
+//
+// 构建信息同时以 key=value 形式写入日志，便于日志平台按提交或版本检索
+func printStartupBanner() {
+	info := buildinfo.Get()
+	fmt.Println("HXLOS Cloud Storage - 启动中...")
+	fmt.Printf("  版本: %s  提交: %s  构建时间: %s  %s %s\n",
+		info.Version, info.ShortCommit(), info.BuildTime, info.GoVersion, info.Platform)
+	log.Printf("build_info version=%s commit=%s build_time=%s go_version=%s platform=%s modified=%t",
+		info.Version, info.GitCommit, info.BuildTime, info.GoVersion, info.Platform, info.Modified)
+}
+
+// initTokenStore 创建全局令牌吊销存储、刷新令牌登记存储和登录会话存储
+//
+// 吊销记录保留到刷新令牌的最长有效期；Redis未初始化时使用进程内存储，
+// 多实例部署下吊销只在发起的实例生效
+func initTokenStore() {
+	ttl := utils.DefaultRefreshExpiry
+	if hours := config.AppConfig.JWT.RefreshExpireHours; hours > 0 {
+		ttl = time.Duration(hours) * time.Hour
+	}
+
+	var store cache.TokenStore
+	var refreshStore cache.RefreshTokenStore
+	var sessionStore cache.SessionStore
+	var challengeStore cache.LoginChallengeStore
+	var ssoStateStore cache.SSOStateStore
+	var mergeTokenStore cache.MergeTokenStore
+	if cache.RedisClient != nil {
+		store = cache.NewRedisTokenStore(cache.RedisClient, ttl)
+		refreshStore = cache.NewRedisRefreshTokenStore(cache.RedisClient)
+		sessionStore = cache.NewRedisSessionStore(cache.RedisClient)
+		challengeStore = cache.NewRedisLoginChallengeStore(cache.RedisClient)
+		ssoStateStore = cache.NewRedisSSOStateStore(cache.RedisClient)
+		mergeTokenStore = cache.NewRedisMergeTokenStore(cache.RedisClient)
+	} else {
+		store = cache.NewMemoryTokenStore(ttl)
+		refreshStore = cache.NewMemoryRefreshTokenStore()
+		sessionStore = cache.NewMemorySessionStore()
+		challengeStore = cache.NewMemoryLoginChallengeStore()
+		ssoStateStore = cache.NewMemorySSOStateStore()
+		mergeTokenStore = cache.NewMemoryMergeTokenStore()
+	}
+	cache.SetDefaultTokenStore(store)
+	cache.SetDefaultRefreshTokenStore(refreshStore)
+	cache.SetDefaultSessionStore(sessionStore)
+	cache.SetDefaultLoginChallengeStore(challengeStore)
+	cache.SetDefaultSSOStateStore(ssoStateStore)
+	cache.SetDefaultMergeTokenStore(mergeTokenStore)
+	backend := "memory"
+	if cache.RedisClient != nil {
+		backend = "redis"
+	}
+	log.Printf("Token store initialized: backend=%s, shared=%v", backend, cache.RedisClient != nil)
+}
+
+// initSearchHistoryStore 创建全局搜索历史存储，历史保留 search_history TTL
+func initSearchHistoryStore() {
+	ttl := cache.NewTTLManager().GetTTL("search_history")
+	if cache.RedisClient != nil {
+		cache.SetDefaultSearchHistoryStore(cache.NewRedisSearchHistoryStore(cache.RedisClient, ttl))
+	} else {
+		cache.SetDefaultSearchHistoryStore(cache.NewMemorySearchHistoryStore(ttl))
+	}
+}
+
+// initClipboardStore 创建全局剪贴板存储，剪贴板保留 clipboard TTL
+func initClipboardStore() {
+	ttl := cache.NewTTLManager().GetTTL("clipboard")
+	if cache.RedisClient != nil {
+		cache.SetDefaultClipboardStore(cache.NewRedisClipboardStore(cache.RedisClient, ttl))
+	} else {
+		cache.SetDefaultClipboardStore(cache.NewMemoryClipboardStore(ttl))
+	}
+}
+
+// startEmailService 创建并启动全局邮件服务
+//
+// Redis可用时死信保存在Redis中，多实例共享且重启后不丢失；管理员修改的模板保存在数据库中；SMTP配置不完整时不创建邮件服务，
+// 记录日志后继续启动，此时依赖邮件的功能返回邮件服务不可用
+func startEmailService(ctx context.Context) {
+	emailConfig := config.AppConfig.Email
+	serviceConfig := email.DefaultEmailConfig()
+	serviceConfig.SMTP.Host = emailConfig.SMTP.Host
+	serviceConfig.SMTP.Port = emailConfig.SMTP.Port
+	serviceConfig.SMTP.Username = emailConfig.SMTP.Username
+	serviceConfig.SMTP.Password = emailConfig.SMTP.Password
+	serviceConfig.From = emailConfig.SMTP.FromEmail
+	if emailConfig.SMTP.FromName != "" {
+		serviceConfig.FromName = emailConfig.SMTP.FromName
+	}
+	if emailConfig.TemplateDir != "" {
+		serviceConfig.TemplateDir = emailConfig.TemplateDir
+	}
+	if emailConfig.DefaultLanguage != "" {
+		serviceConfig.DefaultLanguage = emailConfig.DefaultLanguage
+	}
+	routing := emailConfig.Routing
+	for _, route := range routing.Routes {
+		serviceConfig.Routes = append(serviceConfig.Routes, email.RouteConfig{
+			Name: route.Name,
+			SMTP: email.SMTPConfig{
+				Host:     route.SMTP.Host,
+				Port:     route.SMTP.Port,
+				Username: route.SMTP.Username,
+				Password: route.SMTP.Password,
+				UseTLS:   true,
+			},
+			From:     route.SMTP.FromEmail,
+			FromName: route.SMTP.FromName,
+			Domains:  route.Domains,
+		})
+	}
+	serviceConfig.Routing = email.RoutingOptions{
+		FailureThreshold: routing.FailureThreshold,
+		MinSamples:       routing.MinSamples,
+		Window:           routing.Window,
+		DemoteDuration:   routing.DemoteDuration,
+	}
+
+	if err := serviceConfig.Validate(); err != nil {
+		log.Printf("Email service disabled: %v", err)
+		return
+	}
+
+	var store email.DeadLetterStore
+	if cache.RedisClient != nil {
+		store = email.NewRedisDeadLetterStore(cache.RedisClient)
+	}
+	deadLetter := emailConfig.DeadLetter
+	alerter := email.NewDeadLetterAlerter(email.DeadLetterAlertOptions{
+		WarningThreshold:  deadLetter.WarningThreshold,
+		CriticalThreshold: deadLetter.CriticalThreshold,
+		Cooldown:          deadLetter.AlertCooldown,
+	}, notifyEmailDeadLetters)
+	email.SetGlobalDeadLetterStore(store, alerter)
+	email.SetGlobalTemplateStore(systemrepo.NewEmailTemplateRepository(database.GetDB()))
+
+	if err := email.InitializeGlobalEmailService(serviceConfig); err != nil {
+		log.Printf("Failed to initialize email service: %v", err)
+		return
+	}
+	if err := email.StartGlobalEmailService(ctx); err != nil {
+		log.Printf("Email service not started: %v", err)
+		return
+	}
+	log.Printf("Email service started: dead letters shared=%v, routes=%d", store != nil, len(serviceConfig.Routes))
+}
+
+// notifyEmailDeadLetters 死信数量达到阈值时记录告警日志，由日志平台按级别转发告警
+func notifyEmailDeadLetters(level string, size int64) {
+	if logger.Logger == nil {
+		log.Printf("Email dead letter queue reached %s threshold: size=%d", level, size)
+		return
+	}
+	fields := []zap.Field{zap.String("alert_level", level), zap.Int64("dead_letters", size)}
+	if level == email.DeadLetterAlertCritical {
+		logger.Logger.Error("Email dead letter queue reached critical threshold", fields...)
+		return
+	}
+	logger.Logger.Warn("Email dead letter queue reached warning threshold", fields...)
+}
+
+// initTwoFactor 创建全局两步验证服务
+func initTwoFactor() {
+	db := database.GetDB()
+	userRepo := userrepo.NewUserRepository(db)
+	user.SetDefaultTwoFactorService(user.NewTwoFactorService(
+		userrepo.NewTwoFactorRepository(db),
+		verification.NewVerificationService(db, nil, nil, nil, logger.Logger),
+		user.NewUserService(userRepo, nil, db),
+		config.AppConfig.App.Name,
+		nil,
+	))
+}
+
+// initSMS 根据短信服务配置设置全局手机验证码选项，配置无效时不启用短信验证码
+func initSMS() {
+	cfg := config.AppConfig.ThirdParty.SMS
+	sender, err := sms.FromConfig(cfg)
+	if err != nil {
+		log.Printf("SMS verification disabled: %v", err)
+		return
+	}
+	if sender == nil {
+		return
+	}
+	verification.SetDefaultPhoneOptions(verification.PhoneOptionsFromConfig(cfg, sender))
+	log.Printf("SMS verification enabled: provider=%s", cfg.Provider)
+}
+
+// initReadOnlyMode 创建全局只读模式开关，由配置 server.read_only 或特性开关 read_only_mode 开启
+func initReadOnlyMode() {
+	db := database.GetDB()
+	flags := featureflagsvc.NewFeatureFlagService(
+		systemrepo.NewFeatureFlagRepository(db),
+		userrepo.NewUserRepository(db),
+		logger.Logger,
+	)
+	// 任一实例修改特性开关后立即重新加载，不必等待缓存过期
+	if bus := cache.DefaultInvalidationBus(); bus != nil {
+		bus.Subscribe(cache.InvalidationFeatureFlags, func(context.Context, *cache.Invalidation) {
+			flags.Refresh()
+		})
+	}
+
+	readonly.SetDefault(readonly.NewMode(config.AppConfig.Server.ReadOnly, flags, logger.Logger))
+	if config.AppConfig.Server.ReadOnly {
+		log.Printf("Read-only mode enabled by configuration, write requests will be rejected")
+	}
+}
+
+// initStorageQuota 创建全局存储配额记账服务
+func initStorageQuota() {
+	db := database.GetDB()
+	user.SetDefaultStorageQuotaService(user.NewStorageQuotaService(
+		userrepo.NewStorageReservationRepository(db),
+		userrepo.NewUserRepository(db),
+		nil,
+	))
+}
+
+// initSSO 创建全局企业单点登录和目录同步服务，未启用单点登录时不创建
+func initSSO() {
+	if !config.AppConfig.SSO.Enabled {
+		return
+	}
+	db := database.GetDB()
+	sso.SetDefault(sso.NewService(
+		userrepo.NewSSORepository(db),
+		userrepo.NewUserRepository(db),
+		cache.DefaultSSOStateStore(),
+		nil,
+		sso.OptionsFromConfig(config.AppConfig.SSO),
+		nil,
+	))
+	sso.SetDefaultSCIM(sso.NewSCIMService(
+		userrepo.NewSSORepository(db),
+		userrepo.NewSCIMRepository(db),
+		userrepo.NewUserRepository(db),
+		cache.DefaultTokenStore(),
+		sso.SCIMOptions{},
+		nil,
+	))
+	log.Printf("SSO enabled: redirect_url=%s", config.AppConfig.SSO.RedirectURL)
+}
+
+// initLDAP 启用LDAP时创建企业目录认证，目录中没有的用户仍使用本地账户登录
+func initLDAP() {
+	ldapConfig := config.AppConfig.LDAP
+	if !ldapConfig.Enabled {
+		return
+	}
+	db := database.GetDB()
+	sso.SetDefaultLDAP(sso.NewLDAPAuthenticator(
+		nil,
+		userrepo.NewUserRepository(db),
+		userrepo.NewSCIMRepository(db),
+		sso.LDAPOptionsFromConfig(ldapConfig),
+		nil,
+	))
+	log.Printf("LDAP authentication enabled: url=%s, base_dn=%s, jit=%t", ldapConfig.URL, ldapConfig.BaseDN, ldapConfig.JITEnabled)
+}
+
+// startArchiveTransitions 启动归档扫描，未启用归档或OSS时不启动
+//
+// 首次扫描在一个间隔后进行，避免与启动流量叠加；多实例同时扫描时
+// 归档操作是幂等的，只会产生重复的存储请求
+func startArchiveTransitions(ctx context.Context) {
+	storageConfig := config.AppConfig.Storage
+	if !storageConfig.Archive.Enabled || !storageConfig.OSS.Enabled {
+		return
+	}
+
+	archiver, err := storage.NewS3Archiver(storage.S3OptionsFromConfig(storageConfig.OSS), nil)
+	if err != nil {
+		log.Printf("Archive transitions disabled: %v", err)
+		return
+	}
+	service := filesvc.NewArchiveService(
+		filerepo.NewFileRepository(database.GetDB()),
+		archiver,
+		filesvc.ArchiveOptionsFromConfig(storageConfig.Archive),
+		nil,
+	)
+
+	interval := storageConfig.Archive.ScanInterval
+	if interval <= 0 {
+		interval = defaultArchiveScanInterval
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				report, err := service.TransitionColdFiles(ctx)
+				if err != nil {
+					log.Printf("Archive transition failed: %v", err)
+					continue
+				}
+				if report.Scanned > 0 {
+					log.Printf("Archive transition: %d scanned, %d archived, %d failed",
+						report.Scanned, report.Archived, report.Failed)
+				}
+			}
+		}
+	}()
+	log.Printf("Archive transitions started: class=%s, interval=%s", storageConfig.Archive.StorageClass, interval)
+}
+
+// recoverLocalStorage 处理本地存储上次崩溃遗留的未完成写入并核对分片记录，使用其他存储后端时跳过
+func recoverLocalStorage() {
+	if !isLocalStorageBackend() {
+		return
+	}
+	store, err := storage.NewLocalStorage(config.AppConfig.Storage.Local.RootPath)
+	if err != nil {
+		log.Printf("Local storage recovery skipped: %v", err)
+		return
+	}
+
+	ctx := context.Background()
+	report, err := store.Recover(ctx)
+	if err != nil {
+		// 已处理的部分仍需核对数据库记录
+		log.Printf("Local storage recovery failed: %v", err)
+		if report == nil {
+			return
+		}
+	}
+	result, err := filesvc.ReconcileStorageRecovery(ctx, report, store,
+		filerepo.NewUploadChunkRepository(database.GetDB()), logger.Logger)
+	if err != nil {
+		log.Printf("Local storage reconciliation failed: %v", err)
+	}
+	if result.Repairs() > 0 {
+		log.Printf("Local storage recovery: completed=%d, discarded=%d, orphan_temps=%d, orphan_chunks=%d, chunk_records_reset=%d",
+			result.Completed, result.Discarded, result.OrphanTemps, result.OrphanChunks, result.ChunkRowsReset)
+	}
+}
+
+// startStorageHealth 启动存储后端健康检查，之后创建的存储按熔断状态读写并在主存储不可用时从副本读取
+//
+// 未启用健康检查或主存储不可用时不启动；副本创建失败时只检查主存储
+func startStorageHealth(ctx context.Context) {
+	storageConfig := config.AppConfig.Storage
+	if !storageConfig.Health.Enabled {
+		return
+	}
+	primary, err := storage.NewPrimaryFromConfig(storageConfig)
+	if err != nil {
+		log.Printf("Storage health checks disabled: %v", err)
+		return
+	}
+	replica, err := storage.NewReplicaFromConfig(storageConfig)
+	if err != nil {
+		log.Printf("Storage replica disabled: %v", err)
+		replica = nil
+	}
+
+	monitor := storage.NewHealthMonitor(primary, replica, storage.HealthOptionsFromConfig(storageConfig.Health), logger.Logger)
+	storage.SetDefaultHealthMonitor(monitor)
+	go monitor.Run(ctx)
+	log.Printf("Storage health checks started: primary=%s, replica=%v", primary.Type(), replica != nil)
+}
+
+// startChunkCleanup 启动过期上传分片清理，存储不可用时不启动
+//
+// 多实例同时清理时删除操作是幂等的，重复删除不会出错
+func startChunkCleanup(ctx context.Context, scheduler *maintenance.Scheduler) {
+	store, err := storage.NewFromConfig(config.AppConfig.Storage)
+	if err != nil {
+		log.Printf("Chunk cleanup disabled: %v", err)
+		return
+	}
+	options := filesvc.ChunkedUploadOptionsFromConfig(config.AppConfig.Storage.Upload)
+	options.TTLTuner = filesvc.DefaultUploadTTLTuner()
+	service := filesvc.NewChunkedUploadService(
+		filerepo.NewFileRepository(database.GetDB()),
+		filerepo.NewUploadChunkRepository(database.GetDB()),
+		user.DefaultStorageQuotaService(),
+		store,
+		nil,
+		nil,
+		options,
+		nil,
+		nil,
+		nil,
+	)
+
+	if scheduler != nil {
+		// 本地存储的分片只在接收上传的实例上，每个实例各自清理
+		task := maintenance.Task{
+			Name:     maintenance.TaskUploadChunks,
+			Interval: chunkCleanupInterval,
+			Local:    isLocalStorageBackend(),
+			Run: func(ctx context.Context) (int64, error) {
+				removed, err := service.CleanupExpired(ctx)
+				return int64(removed), err
+			},
+		}
+		if err := scheduler.Register(task); err != nil {
+			log.Printf("Failed to register chunk cleanup: %v", err)
+		}
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(chunkCleanupInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				removed, err := service.CleanupExpired(ctx)
+				if err != nil {
+					log.Printf("Chunk cleanup failed: %v", err)
+					continue
+				}
+				if removed > 0 {
+					log.Printf("Chunk cleanup: %d expired chunks removed", removed)
+				}
+			}
+		}
+	}()
+	log.Printf("Chunk cleanup started: interval=%s", chunkCleanupInterval)
+}
+
+// isLocalStorageBackend 检查是否使用本地存储后端
+func isLocalStorageBackend() bool {
+	backend := config.AppConfig.Storage.Backend
+	return backend == "" || backend == storage.StorageTypeLocal
+}
+
+// newMaintenanceScheduler 创建全局维护任务调度器并注册清理任务，未启用时返回nil
+//
+// 调度器在路由设置完成后启动；Redis存储依靠键过期清理，只有进程内存储需要注册
+func newMaintenanceScheduler() *maintenance.Scheduler {
+	maintenanceConfig := config.AppConfig.Maintenance
+	if !maintenanceConfig.Enabled {
+		return nil
+	}
+
+	db := database.GetDB()
+	scheduler := maintenance.NewScheduler(maintenance.OptionsFromConfig(maintenanceConfig), logger.Logger)
+	// 多实例共享Redis时，同一任务每个间隔只由一个实例执行
+	if cache.RedisClient != nil {
+		scheduler.SetLocker(maintenance.NewRedisLocker(cache.RedisClient))
+	} else {
+		log.Println("WARNING: Maintenance scheduler has no distributed lock (Redis unavailable), every instance runs every task")
+	}
+	sources := maintenance.Sources{
+		Codes:    verification.NewVerificationService(db, nil, nil, nil, logger.Logger),
+		Sessions: userrepo.NewUserRepository(db),
+		Shares:   filerepo.NewShareRepository(db),
+	}
+	if pruner, ok := cache.DefaultTokenStore().(maintenance.Pruner); ok {
+		sources.Tokens = append(sources.Tokens, pruner)
+	}
+	if pruner, ok := cache.DefaultRefreshTokenStore().(maintenance.Pruner); ok {
+		sources.Tokens = append(sources.Tokens, pruner)
+	}
+	if err := maintenance.RegisterCleanupTasks(scheduler, sources); err != nil {
+		log.Printf("Maintenance tasks disabled: %v", err)
+		return nil
+	}
+	maintenance.SetDefault(scheduler)
+	registerBackupTask(scheduler)
+	if quotaService := user.DefaultStorageQuotaService(); quotaService != nil {
+		task := maintenance.Task{Name: maintenance.TaskStorageReservations, Run: func(ctx context.Context) (int64, error) {
+			return quotaService.CleanupExpired(ctx, scheduler.BatchSize())
+		}}
+		if err := scheduler.Register(task); err != nil {
+			log.Printf("Failed to register storage reservation cleanup: %v", err)
+		}
+	}
+
+	log.Printf("Maintenance scheduler created: interval=%s, batch size=%d", scheduler.Interval(), scheduler.BatchSize())
+	return scheduler
+}
+
+// startJobQueue 创建全局后台任务队列并开始执行任务，ctx取消后丢弃排队任务并取消正在执行的任务
+func startJobQueue(ctx context.Context) *jobs.Queue {
+	queue := jobs.NewQueue(jobs.OptionsFromConfig(config.AppConfig.Jobs), logger.Logger)
+	queue.Start(ctx)
+	jobs.SetDefault(queue)
+	log.Printf("Job queue started with %d configured pools", len(config.AppConfig.Jobs.Pools))
+	return queue
+}
+
+// registerBackupTask 启用元数据备份时注册定期备份任务，存储或密钥不可用时只记录日志
+func registerBackupTask(scheduler *maintenance.Scheduler) {
+	backupConfig := config.AppConfig.Backup
+	if !backupConfig.Enabled {
+		return
+	}
+
+	store, err := storage.NewFromConfig(config.AppConfig.Storage)
+	if err != nil {
+		log.Printf("Metadata backup disabled: storage unavailable: %v", err)
+		return
+	}
+	service, err := backup.NewService(database.GetDB(), store, backup.OptionsFromConfig(backupConfig), logger.Logger)
+	if err != nil {
+		log.Printf("Metadata backup disabled: %v", err)
+		return
+	}
+
+	task := maintenance.Task{Name: maintenance.TaskBackup, Interval: service.Interval(), Run: func(ctx context.Context) (int64, error) {
+		entry, err := service.Run(ctx)
+		if err != nil {
+			return 0, err
+		}
+		var rows int64
+		for _, count := range entry.Rows {
+			rows += count
+		}
+		return rows, nil
+	}}
+	if err := scheduler.Register(task); err != nil {
+		log.Printf("Failed to register metadata backup: %v", err)
+		return
+	}
+	log.Printf("Metadata backup scheduled: interval=%s", service.Interval())
+}
+
+// startAPIUsage 启用API用量统计并定期将内存计数写入日汇总表
+//
+// 返回的函数停止定时写入并写入剩余计数，应在HTTP服务器关闭后调用
+func startAPIUsage() func(context.Context) {
+	usageConfig := config.AppConfig.Monitoring.APIUsage
+	if !usageConfig.Enabled {
+		return func(context.Context) {}
+	}
+
+	service := usagesvc.NewUsageService(
+		systemrepo.NewAPIUsageRepository(database.GetDB()),
+		userrepo.NewUserRepository(database.GetDB()),
+		usagesvc.OptionsFromConfig(usageConfig),
+		nil,
+	)
+	usagesvc.SetDefault(service)
+
+	interval := usageConfig.FlushInterval
+	if interval <= 0 {
+		interval = defaultAPIUsageFlushInterval
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := service.Flush(ctx); err != nil {
+					log.Printf("API usage flush failed: %v", err)
+				}
+			}
+		}
+	}()
+	log.Printf("API usage tracking started: flush interval=%s", interval)
+
+	return func(shutdownCtx context.Context) {
+		cancel()
+		<-done
+		if err := service.Flush(shutdownCtx); err != nil {
+			log.Printf("Final API usage flush failed: %v", err)
+		}
+	}
+}
+
+// warmReferenceData 创建全局参考数据预热器并完成首次加载
+//
+// 预热失败不阻止启动，失败的数据源会在首次读取或管理员重新预热时加载
+func warmReferenceData() {
+	var cacheManager *cache.CacheManager
+	if cache.RedisClient != nil {
+		cacheManager = cache.NewCacheManager()
+	}
+
+	db := database.GetDB()
+	warmer := cache.NewWarmer(cacheManager, logger.Logger)
+	warmup.RegisterReferenceSources(warmer, warmup.Repositories{
+		Settings: systemrepo.NewSettingRepository(db),
+		Policies: systemrepo.NewStoragePolicyRepository(db),
+		Flags:    systemrepo.NewFeatureFlagRepository(db),
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), referenceWarmupTimeout)
+	defer cancel()
+	report := warmer.WarmAll(ctx)
+	for _, result := range report.Results {
+		if result.Error != "" {
+			log.Printf("Failed to warm reference data %s: %s", result.Source, result.Error)
+		}
+	}
+	log.Printf("Reference data warmed: %d sources, %d failed, took %s",
+		len(report.Results), report.Failed(), report.Duration)
+
+	// 其他实例重新预热后清除本地副本，下次读取时重新加载
+	if bus := cache.DefaultInvalidationBus(); bus != nil {
+		bus.Subscribe(cache.InvalidationReferenceData, func(_ context.Context, msg *cache.Invalidation) {
+			warmer.Evict(msg.Targets...)
+		})
+	}
+
+	warmup.SetDefault(warmer)
+}
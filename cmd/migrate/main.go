@@ -1,22 +1,48 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io/fs"
 	"log"
 	"os"
 
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/backup"
 	"cloudpan/internal/pkg/config"
 	"cloudpan/internal/pkg/database"
+	"cloudpan/internal/pkg/database/migrate"
+	"cloudpan/internal/pkg/storage"
+	"cloudpan/migrations"
 )
 
+// availableActions 支持的操作
+const availableActions = "up, goto, rollback, force, version, migrate, status, validate, drop, reencrypt, hash-share-passwords, backup, list-backups, restore"
+
+// versionedOptions 版本化迁移参数
+type versionedOptions struct {
+	path    string // 迁移文件目录，为空时使用内嵌的迁移文件
+	version int64  // goto/force 的目标版本
+	steps   int    // rollback 的步数
+}
+
 func main() {
 	// 定义命令行参数
 	var (
-		action      = flag.String("action", "migrate", "Action to perform: migrate, status, validate, drop")
+		action      = flag.String("action", "up", "Action to perform: "+availableActions)
 		configPath  = flag.String("config", "configs/config.yaml", "Path to config file")
 		dropFirst   = flag.Bool("drop", false, "Drop tables before migration")
 		createIndex = flag.Bool("index", true, "Create indexes after migration")
+		batchSize   = flag.Int("batch", 500, "Batch size for reencrypt and hash-share-passwords")
+		backupName  = flag.String("backup", backup.LatestBackup, "Backup name or storage path to restore")
+		confirm     = flag.Bool("confirm", false, "Apply the restore; without it restore only validates the backup")
+		dev         = flag.Bool("dev", false, "Allow the development-only migrate (AutoMigrate) action")
+		path        = flag.String("path", "", "Directory of versioned SQL migrations; defaults to the migrations embedded in the binary")
+		version     = flag.Int64("version", -1, "Target version for goto and force")
+		steps       = flag.Int("steps", 1, "Number of migrations to roll back")
 	)
 	flag.Parse()
 
@@ -27,7 +53,8 @@ func main() {
 	defer database.Close()
 
 	// 执行操作
-	if err := executeAction(*action, *dropFirst, *createIndex); err != nil {
+	versioned := versionedOptions{path: *path, version: *version, steps: *steps}
+	if err := executeAction(*action, *dropFirst, *createIndex, *dev, versioned, *batchSize, *backupName, *confirm); err != nil {
 		log.Fatalf("Operation failed: %v", err)
 	}
 }
@@ -48,9 +75,14 @@ func initSystem(configPath string) error {
 }
 
 // executeAction 执行操作
-func executeAction(action string, dropFirst, createIndex bool) error {
+func executeAction(action string, dropFirst, createIndex, dev bool, versioned versionedOptions, batchSize int, backupName string, confirm bool) error {
 	switch action {
+	case "up", "goto", "rollback", "force", "version":
+		return handleVersioned(action, versioned)
 	case "migrate":
+		if !dev {
+			return errors.New("migrate runs GORM AutoMigrate and is for development only; use -action up for versioned migrations, or pass -dev")
+		}
 		return handleMigration(dropFirst, createIndex)
 	case "status":
 		return handleStatus()
@@ -58,11 +90,97 @@ func executeAction(action string, dropFirst, createIndex bool) error {
 		return handleValidation()
 	case "drop":
 		return handleDrop()
+	case "reencrypt":
+		return handleReencrypt(batchSize)
+	case "hash-share-passwords":
+		return handleHashSharePasswords(batchSize)
+	case "backup":
+		return handleBackup()
+	case "list-backups":
+		return handleListBackups()
+	case "restore":
+		return handleRestore(backupName, confirm)
 	default:
 		return handleUnknownAction(action)
 	}
 }
 
+// handleVersioned 执行版本化迁移操作
+func handleVersioned(action string, opts versionedOptions) error {
+	migrator, err := newMigrator(opts.path)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	switch action {
+	case "up":
+		err = migrator.Up(ctx)
+	case "goto", "force":
+		if opts.version < 0 {
+			return fmt.Errorf("-version is required for %s", action)
+		}
+		if action == "goto" {
+			err = migrator.Goto(ctx, uint64(opts.version))
+		} else {
+			err = migrator.Force(ctx, uint64(opts.version))
+		}
+	case "rollback":
+		err = migrator.Rollback(ctx, opts.steps)
+	}
+	if err != nil {
+		return err
+	}
+	return showVersionedStatus(ctx, migrator)
+}
+
+// newMigrator 加载迁移文件并创建迁移执行器
+func newMigrator(path string) (*migrate.Migrator, error) {
+	var source fs.FS = migrations.FS
+	if path != "" {
+		source = os.DirFS(path)
+	}
+	loaded, err := migrate.Load(source)
+	if err != nil {
+		return nil, err
+	}
+
+	logger, err := zap.NewProduction()
+	if err != nil {
+		logger = zap.NewNop()
+	}
+	return migrate.NewMigrator(database.GetDB(), loaded, logger), nil
+}
+
+// showVersionedStatus 显示当前版本和每个迁移的执行状态
+func showVersionedStatus(ctx context.Context, migrator *migrate.Migrator) error {
+	statuses, current, dirty, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Schema version: %d", current)
+	if dirty {
+		fmt.Print(" (dirty: fix the schema manually, then run -action force -version N)")
+	}
+	fmt.Println()
+	for _, status := range statuses {
+		state := "pending"
+		if status.Applied {
+			state = "applied"
+		}
+		reversible := ""
+		if !status.HasDown {
+			reversible = "\tirreversible"
+		}
+		if status.Irreversible != "" {
+			reversible += " (" + status.Irreversible + ")"
+		}
+		fmt.Printf("%03d_%s\t%s%s\n", status.Version, status.Name, state, reversible)
+	}
+	return nil
+}
+
 // handleMigration 处理迁移操作
 func handleMigration(dropFirst, createIndex bool) error {
 	if err := runMigration(dropFirst, createIndex); err != nil {
@@ -95,15 +213,120 @@ func handleDrop() error {
 	return nil
 }
 
+// handleReencrypt 使用当前密钥版本重加密敏感字段
+func handleReencrypt(batchSize int) error {
+	results, err := database.ReencryptSensitiveColumns(database.GetDB(), batchSize)
+	for _, stats := range results {
+		fmt.Printf("%s: scanned %d rows, re-encrypted %d rows\n", stats.Table, stats.Scanned, stats.Updated)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to re-encrypt sensitive columns: %w", err)
+	}
+	fmt.Println("Re-encryption completed successfully")
+	return nil
+}
+
+// handleHashSharePasswords 将历史明文分享密码替换为BCrypt哈希
+func handleHashSharePasswords(batchSize int) error {
+	stats, err := database.HashPlaintextSharePasswords(database.GetDB(), batchSize)
+	if stats != nil {
+		fmt.Printf("%s: scanned %d rows, hashed %d passwords\n", stats.Table, stats.Scanned, stats.Updated)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to hash share passwords: %w", err)
+	}
+	fmt.Println("Share password hashing completed successfully")
+	return nil
+}
+
+// handleBackup 立即执行一次元数据备份
+func handleBackup() error {
+	service, err := newBackupService()
+	if err != nil {
+		return err
+	}
+	entry, err := service.Run(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to back up metadata: %w", err)
+	}
+	fmt.Printf("Backup %s written to %s (%d bytes)\n", entry.Name, entry.Path, entry.Size)
+	for table, rows := range entry.Rows {
+		fmt.Printf("%s: %d rows\n", table, rows)
+	}
+	return nil
+}
+
+// handleListBackups 列出备份目录中的备份
+func handleListBackups() error {
+	service, err := newBackupService()
+	if err != nil {
+		return err
+	}
+	entries, err := service.List(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to list backups: %w", err)
+	}
+	if len(entries) == 0 {
+		fmt.Println("No backups found")
+		return nil
+	}
+	for _, entry := range entries {
+		fmt.Printf("%s\t%s\t%d bytes\tkey %s\tformat %d\tapp %s\n",
+			entry.Name, entry.CreatedAt.Format("2006-01-02 15:04:05"), entry.Size, entry.KeyVersion, entry.FormatVersion, entry.AppVersion)
+	}
+	return nil
+}
+
+// handleRestore 校验备份与当前数据库的兼容性，指定 -confirm 时恢复备份
+func handleRestore(name string, confirm bool) error {
+	service, err := newBackupService()
+	if err != nil {
+		return err
+	}
+	report, err := service.Restore(context.Background(), name, confirm)
+	if report != nil {
+		fmt.Printf("Backup: %s (format %d, app %s, created %s)\n",
+			report.Backup, report.Manifest.FormatVersion, report.Manifest.AppVersion, report.Manifest.CreatedAt.Format("2006-01-02 15:04:05"))
+		for _, warning := range report.Warnings {
+			fmt.Printf("Warning: %s\n", warning)
+		}
+		for table, rows := range report.Rows {
+			fmt.Printf("%s: %d rows\n", table, rows)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to restore backup: %w", err)
+	}
+	if !report.Applied {
+		fmt.Println("Backup is compatible; run again with -confirm to restore it")
+		return nil
+	}
+	fmt.Println("Restore completed successfully")
+	return nil
+}
+
+// newBackupService 按配置创建元数据备份服务
+func newBackupService() (*backup.Service, error) {
+	store, err := storage.NewFromConfig(config.AppConfig.Storage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	service, err := backup.NewService(database.GetDB(), store, backup.OptionsFromConfig(config.AppConfig.Backup), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize backup: %w", err)
+	}
+	return service, nil
+}
+
 // handleUnknownAction 处理未知操作
 func handleUnknownAction(action string) error {
 	fmt.Printf("Unknown action: %s\n", action)
-	fmt.Println("Available actions: migrate, status, validate, drop")
+	fmt.Println("Available actions: " + availableActions)
 	os.Exit(1)
 	return nil
 }
 
-// runMigration 执行迁移
+// runMigration 执行GORM AutoMigrate，仅用于开发环境
 func runMigration(dropFirst, createIndex bool) error {
 	migrationConfig := &database.MigrationConfig{
 		AutoMigrate: true,
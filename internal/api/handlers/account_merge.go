@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/service/audit"
+	"cloudpan/internal/service/user"
+)
+
+// AccountMergeHandler 账户合并处理器
+type AccountMergeHandler struct {
+	securityAudit
+	service user.AccountMergeService
+	logger  *zap.Logger
+}
+
+// NewAccountMergeHandler 创建账户合并处理器
+func NewAccountMergeHandler(service user.AccountMergeService, logger *zap.Logger) *AccountMergeHandler {
+	return &AccountMergeHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// MergeTokenInfo 账户合并令牌
+type MergeTokenInfo struct {
+	// 合并令牌，登录要保留的账户后提交
+	MergeToken string `json:"merge_token" example:"3b5d5c3712955042212316173ccf37be"`
+	// 令牌过期时间，过期后需要重新申请
+	ExpiresAt string `json:"expires_at" example:"2024-01-01T00:10:00Z"`
+}
+
+// MergeAccountRequest 合并账户请求
+type MergeAccountRequest struct {
+	// 在要合并掉的账户中申请的合并令牌
+	MergeToken string `json:"merge_token" binding:"required" example:"3b5d5c3712955042212316173ccf37be"`
+}
+
+// CreateMergeToken 申请账户合并令牌
+//
+// @Summary 申请账户合并令牌
+// @Description 在要合并掉的账户中调用，证明持有该账户。令牌10分钟内有效，只能使用一次；登录要保留的账户后调用合并账户接口提交
+// @Tags 用户
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response{data=MergeTokenInfo} "申请成功"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "账户当前不可合并或模拟登录中"
+// @Failure 500 {object} utils.Response "内部服务器错误"
+// @Router /api/v1/users/me/merge-token [post]
+func (h *AccountMergeHandler) CreateMergeToken(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	token, err := h.service.CreateToken(c.Request.Context(), userID)
+	if err != nil {
+		h.logger.Warn("Failed to create account merge token", zap.Uint("user_id", userID), zap.Error(err))
+		respondServiceError(c, err, "申请合并令牌失败")
+		return
+	}
+	utils.Success(c, MergeTokenInfo{MergeToken: token.Token, ExpiresAt: token.ExpiresAt.UTC().Format(time.RFC3339)})
+}
+
+// MergeAccount 合并账户
+//
+// @Summary 合并账户
+// @Description 在要保留的账户中提交另一个账户申请的合并令牌，把该账户合并到当前账户：
+// @Description 先检查当前账户剩余空间能容纳对方的已用空间，再把对方根目录下的全部文件(分享保持有效)转到当前账户根目录下"<对方用户名> 的文件"文件夹中，
+// @Description 团队成员身份(同一团队保留较高的角色)、团队所有权和单点登录身份关联转到当前账户，对方账户停用并吊销其令牌。
+// @Description 回收站中的文件不转移；有文件转移失败时对方账户不停用，已转移的文件保留，重新申请令牌后可以再次合并
+// @Tags 用户
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body MergeAccountRequest true "合并令牌"
+// @Success 200 {object} utils.Response{data=user.MergeResult} "合并成功"
+// @Failure 400 {object} utils.Response "合并令牌无效或已过期"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "账户当前不可合并、有文件转移失败或模拟登录中"
+// @Failure 413 {object} utils.Response{data=user.QuotaExceededDetails} "当前账户存储空间不足"
+// @Failure 500 {object} utils.Response "内部服务器错误"
+// @Router /api/v1/users/me/merge [post]
+func (h *AccountMergeHandler) MergeAccount(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	var req MergeAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+		return
+	}
+
+	result, err := h.service.Merge(c.Request.Context(), userID, req.MergeToken)
+	if err != nil {
+		h.logger.Warn("Failed to merge accounts", zap.Uint("user_id", userID), zap.Error(err), zap.String("ip", c.ClientIP()))
+		respondServiceError(c, err, "合并账户失败")
+		return
+	}
+
+	h.logger.Info("Accounts merged",
+		zap.Uint("user_id", userID),
+		zap.Uint("source_id", result.SourceID),
+		zap.Int("files", result.Files),
+		zap.String("ip", c.ClientIP()))
+	// 两个账户各记一条，按任一账户查询都能找到合并记录
+	sourceID := strconv.FormatUint(uint64(result.SourceID), 10)
+	details := map[string]interface{}{
+		"target_id":    userID,
+		"files":        result.Files,
+		"storage_used": result.StorageUsed,
+		"teams":        result.Counts.Teams,
+		"owned_teams":  result.Counts.OwnedTeams,
+		"identities":   result.Counts.Identities,
+	}
+	before := map[string]interface{}{"username": result.SourceUsername, "email": result.SourceEmail, "status": "active"}
+	after := map[string]interface{}{"status": "deleted", user.ProfileKeyMergedInto: userID}
+	h.recordSecurity(c, h.logger,
+		&audit.Event{UserID: userID, Action: audit.SecurityActionAccountMerge, ResourceType: audit.ResourceUser,
+			ResourceID: sourceID, ResourceName: result.SourceUsername, Before: before, After: after, Details: details},
+		&audit.Event{UserID: result.SourceID, Action: audit.SecurityActionAccountMerge, ResourceType: audit.ResourceUser,
+			ResourceID: sourceID, ResourceName: result.SourceUsername, Before: before, After: after, Details: details},
+	)
+	utils.SuccessWithMessage(c, "账户已合并", result)
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/cache"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/utils"
+	userrepo "cloudpan/internal/repository/user"
+	"cloudpan/internal/service/audit"
+	"cloudpan/internal/service/user"
+)
+
+// stubAccountMergeService 令牌对应用户2，目标账户剩余空间由 available 决定
+type stubAccountMergeService struct {
+	available int64
+	merged    []uint
+}
+
+func (s *stubAccountMergeService) CreateToken(_ context.Context, userID uint) (*cache.MergeToken, error) {
+	return &cache.MergeToken{Token: "merge-token", UserID: uint64(userID), ExpiresAt: time.Now().Add(user.MergeTokenTTL)}, nil
+}
+
+func (s *stubAccountMergeService) Merge(_ context.Context, userID uint, token string) (*user.MergeResult, error) {
+	if token != "merge-token" {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "合并令牌无效或已过期")
+	}
+	if s.available < 100 {
+		return nil, &user.QuotaExceededError{Usage: &user.StorageUsage{Available: s.available}, Required: 100}
+	}
+	s.merged = append(s.merged, userID)
+	return &user.MergeResult{SourceID: 2, SourceUsername: "alice-oauth", SourceEmail: "alice@oauth.example.com", Files: 3, StorageUsed: 100,
+		Counts: &userrepo.AccountMergeCounts{Teams: 1, Identities: 1}}, nil
+}
+
+func TestAccountMergeHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	service := &stubAccountMergeService{}
+	auditService := &recordingSecurityAudit{}
+	handler := NewAccountMergeHandler(service, zap.NewNop())
+	handler.SetSecurityAuditService(auditService)
+
+	call := func(handle gin.HandlerFunc, userID uint64, body interface{}) (*httptest.ResponseRecorder, utils.Response) {
+		reqBody, _ := json.Marshal(body)
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/users/me/merge", bytes.NewBuffer(reqBody))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Set("user_id", userID)
+		handle(c)
+		return w, decodeShareResponse(t, w)
+	}
+
+	w, resp := call(handler.CreateMergeToken, 2, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	data, ok := resp.Data.(map[string]interface{})
+	require.True(t, ok)
+	assert.Equal(t, "merge-token", data["merge_token"])
+	assert.NotEmpty(t, data["expires_at"])
+
+	w, _ = call(handler.MergeAccount, 1, gin.H{})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w, _ = call(handler.MergeAccount, 1, MergeAccountRequest{MergeToken: "expired"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w, resp = call(handler.MergeAccount, 1, MergeAccountRequest{MergeToken: "merge-token"})
+	assert.Equal(t, utils.CodeQuotaExceeded, resp.Code, "目标账户空间不足")
+	assert.Empty(t, service.merged)
+	assert.Empty(t, auditService.events)
+
+	service.available = 1000
+	w, resp = call(handler.MergeAccount, 1, MergeAccountRequest{MergeToken: "merge-token"})
+	require.Equal(t, http.StatusOK, w.Code)
+	data, ok = resp.Data.(map[string]interface{})
+	require.True(t, ok)
+	assert.Equal(t, float64(2), data["source_id"])
+	assert.Equal(t, []uint{1}, service.merged)
+
+	// 两个账户各有一条审计记录
+	require.Len(t, auditService.events, 2)
+	for i, userID := range []uint{1, 2} {
+		event := auditService.events[i]
+		assert.Equal(t, userID, event.UserID)
+		assert.Equal(t, audit.SecurityActionAccountMerge, event.Action)
+		assert.Equal(t, "2", event.ResourceID)
+	}
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/service/audit"
+)
+
+// 审计日志分页参数
+const (
+	defaultAuditPageSize = 20
+	maxAuditPageSize     = 100
+)
+
+// AdminAuditHandler 管理员操作审计日志处理器
+type AdminAuditHandler struct {
+	service audit.AdminAuditService
+	logger  *zap.Logger
+}
+
+// NewAdminAuditHandler 创建管理员操作审计日志处理器
+func NewAdminAuditHandler(service audit.AdminAuditService, logger *zap.Logger) *AdminAuditHandler {
+	return &AdminAuditHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// ListLogs 查询管理员操作审计日志
+//
+// @Summary 查询管理员操作审计日志
+// @Description 按管理员、操作类型、操作对象和时间范围分页查询管理员的特权操作记录，按时间倒序。记录包含操作前后的值、原因、IP和请求ID，写入后不可修改或删除
+// @Tags 系统
+// @Produce json
+// @Security BearerAuth
+// @Param actor_id query int false "管理员ID"
+// @Param action query string false "操作类型，如 user.suspend、user.quota_change、user.impersonate、feature_flag.change、sso_provider.change"
+// @Param target_type query string false "操作对象类型，如 user、feature_flag、cache、sso_provider"
+// @Param target_id query string false "操作对象ID"
+// @Param from query string false "起始时间(RFC3339，包含)"
+// @Param to query string false "结束时间(RFC3339，不包含)"
+// @Param page query int false "页码，默认1"
+// @Param page_size query int false "每页记录数，默认20，最大100"
+// @Success 200 {object} utils.Response{data=[]models.AdminAuditLog} "查询成功"
+// @Failure 400 {object} utils.Response "查询参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "需要管理员权限"
+// @Failure 500 {object} utils.Response "内部服务器错误"
+// @Router /api/v1/admin/audit-logs [get]
+func (h *AdminAuditHandler) ListLogs(c *gin.Context) {
+	query := audit.Query{
+		Action:     c.Query("action"),
+		TargetType: c.Query("target_type"),
+		TargetID:   c.Query("target_id"),
+	}
+	if value := c.Query("actor_id"); value != "" {
+		actorID, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			utils.ErrorWithMessage(c, utils.CodeBadRequest, "actor_id格式错误")
+			return
+		}
+		query.ActorID = uint(actorID)
+	}
+	var ok bool
+	if query.From, ok = parseAuditTime(c, "from"); !ok {
+		return
+	}
+	if query.To, ok = parseAuditTime(c, "to"); !ok {
+		return
+	}
+	query.Page, _ = strconv.Atoi(c.Query("page"))
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	query.PageSize, _ = strconv.Atoi(c.Query("page_size"))
+	if query.PageSize < 1 {
+		query.PageSize = defaultAuditPageSize
+	}
+	query.PageSize = min(query.PageSize, maxAuditPageSize)
+
+	logs, total, err := h.service.List(c.Request.Context(), query)
+	if err != nil {
+		respondServiceError(c, err, "查询审计日志失败")
+		return
+	}
+
+	utils.SuccessList(c, logs, utils.NewPagination(query.Page, query.PageSize, total))
+}
+
+// parseAuditTime 解析RFC3339格式的时间参数，参数为空时返回零值
+func parseAuditTime(c *gin.Context, name string) (time.Time, bool) {
+	value := c.Query(name)
+	if value == "" {
+		return time.Time{}, true
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, name+"时间格式错误，应为RFC3339")
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// recordAdminAudit 写入管理员操作审计记录
+//
+// 操作者、IP、User-Agent和请求ID从请求上下文填充。service为nil时不记录；
+// 写入失败不影响已完成的操作，在错误日志中保留完整记录以便补录
+func recordAdminAudit(c *gin.Context, service audit.AdminAuditService, logger *zap.Logger, entries ...*audit.Entry) {
+	if service == nil || len(entries) == 0 {
+		return
+	}
+
+	actorID, _ := getCurrentUserID(c)
+	for _, entry := range entries {
+		entry.ActorID = actorID
+		entry.IPAddress = c.ClientIP()
+		entry.UserAgent = c.Request.UserAgent()
+		entry.RequestID = c.GetString("request_id") // 请求ID中间件写入
+	}
+
+	if err := service.Record(c.Request.Context(), entries...); err != nil {
+		for _, entry := range entries {
+			logger.Error("Failed to record admin audit log",
+				zap.Uint("actor_id", entry.ActorID),
+				zap.String("action", entry.Action),
+				zap.String("target_type", entry.TargetType),
+				zap.String("target_id", entry.TargetID),
+				zap.Any("before", entry.Before),
+				zap.Any("after", entry.After),
+				zap.String("reason", entry.Reason),
+				zap.String("request_id", entry.RequestID),
+				zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+	"cloudpan/internal/service/audit"
+)
+
+// recordingAuditService 记录写入和查询条件的审计服务
+type recordingAuditService struct {
+	entries []*audit.Entry
+	query   audit.Query
+	logs    []*models.AdminAuditLog
+	err     error
+}
+
+func (s *recordingAuditService) Record(_ context.Context, entries ...*audit.Entry) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.entries = append(s.entries, entries...)
+	return nil
+}
+
+func (s *recordingAuditService) List(_ context.Context, query audit.Query) ([]*models.AdminAuditLog, int64, error) {
+	s.query = query
+	if s.err != nil {
+		return nil, 0, s.err
+	}
+	return s.logs, int64(len(s.logs)), nil
+}
+
+func setupAdminAuditRouter(service audit.AdminAuditService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/admin/audit-logs", NewAdminAuditHandler(service, zap.NewNop()).ListLogs)
+	return router
+}
+
+func TestAdminAuditHandler_ListLogs(t *testing.T) {
+	t.Run("filters", func(t *testing.T) {
+		service := &recordingAuditService{logs: []*models.AdminAuditLog{
+			{ID: 3, ActorID: 1, Action: audit.ActionUserSuspend, TargetType: audit.TargetUser, TargetID: "42"},
+		}}
+
+		w := httptest.NewRecorder()
+		setupAdminAuditRouter(service).ServeHTTP(w, httptest.NewRequest(http.MethodGet,
+			"/admin/audit-logs?actor_id=1&action=user.suspend&target_type=user&target_id=42"+
+				"&from=2024-05-01T00:00:00Z&to=2024-05-02T00:00:00%2B08:00&page=2&page_size=500", nil))
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, audit.Query{
+			ActorID:    1,
+			Action:     audit.ActionUserSuspend,
+			TargetType: audit.TargetUser,
+			TargetID:   "42",
+			From:       time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+			To:         time.Date(2024, 5, 1, 16, 0, 0, 0, time.UTC),
+			Page:       2,
+			PageSize:   maxAuditPageSize,
+		}, normalizeAuditQuery(service.query))
+		var resp utils.ListResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.NotNil(t, resp.Pagination)
+		assert.Equal(t, int64(1), resp.Pagination.TotalCount)
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		service := &recordingAuditService{}
+
+		w := httptest.NewRecorder()
+		setupAdminAuditRouter(service).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/audit-logs", nil))
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, audit.Query{Page: 1, PageSize: defaultAuditPageSize}, service.query)
+	})
+
+	t.Run("invalid parameters", func(t *testing.T) {
+		for _, query := range []string{"actor_id=abc", "from=yesterday", "to=2024-05-01"} {
+			w := httptest.NewRecorder()
+			setupAdminAuditRouter(&recordingAuditService{}).ServeHTTP(w,
+				httptest.NewRequest(http.MethodGet, "/admin/audit-logs?"+query, nil))
+			assert.Equal(t, http.StatusBadRequest, w.Code, query)
+		}
+	})
+
+	t.Run("invalid range", func(t *testing.T) {
+		service := &recordingAuditService{err: pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "起始时间必须早于结束时间")}
+
+		w := httptest.NewRecorder()
+		setupAdminAuditRouter(service).ServeHTTP(w, httptest.NewRequest(http.MethodGet,
+			"/admin/audit-logs?from=2024-05-02T00:00:00Z&to=2024-05-01T00:00:00Z", nil))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, utils.CodeValidationError, decodeShareResponse(t, w).Code)
+	})
+}
+
+// normalizeAuditQuery 统一时区，便于比较解析出的时间
+func normalizeAuditQuery(query audit.Query) audit.Query {
+	query.From = query.From.UTC()
+	query.To = query.To.UTC()
+	return query
+}
+
+func TestRecordAdminAudit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	service := &recordingAuditService{}
+	router := gin.New()
+	router.POST("/action", func(c *gin.Context) {
+		c.Set("user_id", uint64(9))
+		c.Set("request_id", "req-1")
+		recordAdminAudit(c, service, zap.NewNop(), &audit.Entry{
+			Action:     audit.ActionUserSuspend,
+			TargetType: audit.TargetUser,
+			TargetID:   "42",
+		})
+		c.Status(http.StatusNoContent)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/action", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	req.Header.Set("User-Agent", "admin-console")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	require.Len(t, service.entries, 1)
+	entry := service.entries[0]
+	assert.Equal(t, uint(9), entry.ActorID)
+	assert.Equal(t, "192.0.2.1", entry.IPAddress)
+	assert.Equal(t, "admin-console", entry.UserAgent)
+	assert.Equal(t, "req-1", entry.RequestID)
+
+	// 写入失败不影响响应
+	service.err = errors.New("database unavailable")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/action", nil))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
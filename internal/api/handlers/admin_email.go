@@ -0,0 +1,350 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/email"
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/service/audit"
+)
+
+// 死信列表分页和批量操作限制
+const (
+	defaultDeadLetterPageSize = 20
+	maxDeadLetterPageSize     = 100
+	maxDeadLetterBatch        = 500
+)
+
+// AdminEmailHandler 管理员查看和处理邮件死信队列的处理器
+type AdminEmailHandler struct {
+	queue  email.DeadLetterQueue
+	routes email.RouteMonitor
+	audit  audit.AdminAuditService
+	logger *zap.Logger
+}
+
+// NewAdminEmailHandler 创建邮件死信队列管理处理器
+func NewAdminEmailHandler(queue email.DeadLetterQueue, logger *zap.Logger) *AdminEmailHandler {
+	return &AdminEmailHandler{
+		queue:  queue,
+		logger: logger,
+	}
+}
+
+// SetAuditService 设置管理员操作审计服务，未设置时不记录审计日志
+func (h *AdminEmailHandler) SetAuditService(service audit.AdminAuditService) {
+	h.audit = service
+}
+
+// SetRouteMonitor 设置发送线路监控，未设置时查询线路统计返回空列表
+func (h *AdminEmailHandler) SetRouteMonitor(monitor email.RouteMonitor) {
+	h.routes = monitor
+}
+
+// DeadLetterInfo 死信邮件信息
+//
+// 不返回邮件正文和模板变量，其中可能包含验证码和密码重置链接
+type DeadLetterInfo struct {
+	ID          string     `json:"id"`                  // 邮件ID
+	To          []string   `json:"to"`                  // 收件人
+	Subject     string     `json:"subject,omitempty"`   // 主题，模板邮件为空
+	Template    string     `json:"template,omitempty"`  // 模板名称
+	Priority    int        `json:"priority"`            // 优先级
+	Attempts    int        `json:"attempts"`            // 已尝试发送次数
+	MaxAttempts int        `json:"max_attempts"`        // 最大尝试次数
+	LastError   string     `json:"last_error"`          // 最近一次失败原因
+	Errors      []string   `json:"errors,omitempty"`    // 每次发送失败的原因
+	CreatedAt   time.Time  `json:"created_at"`          // 入队时间
+	FailedAt    *time.Time `json:"failed_at,omitempty"` // 进入死信队列的时间
+}
+
+// RetryDeadLettersRequest 重新发送死信请求
+type RetryDeadLettersRequest struct {
+	IDs []string `json:"ids" binding:"required,min=1,max=500,dive,required"` // 邮件ID
+}
+
+// PurgeDeadLettersRequest 清除死信请求，清空死信队列需显式指定all
+type PurgeDeadLettersRequest struct {
+	IDs []string `json:"ids" binding:"max=500,dive,required"` // 邮件ID
+	All bool     `json:"all"`                                 // 清空死信队列，此时忽略ids
+}
+
+// GetQueueStatus 查询邮件队列状态
+//
+// @Summary 查询邮件队列状态
+// @Description 返回等待发送的邮件数、队列容量、死信数量以及死信数量对应的告警级别和阈值
+// @Tags 系统
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response{data=email.DeadLetterStatus} "获取成功"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "需要管理员权限"
+// @Failure 500 {object} utils.Response "内部服务器错误"
+// @Router /api/v1/admin/email/queue [get]
+func (h *AdminEmailHandler) GetQueueStatus(c *gin.Context) {
+	status, err := h.queue.DeadLetterStatus(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to get email queue status", zap.Error(err))
+		utils.InternalErrorWithMessage(c, "获取邮件队列状态失败")
+		return
+	}
+	utils.Success(c, status)
+}
+
+// ListRoutes 查询邮件发送线路的投递统计
+//
+// @Summary 查询邮件发送线路
+// @Description 按匹配顺序返回按收件人域名配置的发送线路及默认线路的投递统计，包括统计窗口内的失败率和自动降级状态；没有配置发送线路时返回空列表
+// @Tags 系统
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response{data=[]email.RouteStats} "获取成功"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "需要管理员权限"
+// @Router /api/v1/admin/email/routes [get]
+func (h *AdminEmailHandler) ListRoutes(c *gin.Context) {
+	routes := []*email.RouteStats{}
+	if h.routes != nil {
+		routes = append(routes, h.routes.RouteStats()...)
+	}
+	utils.Success(c, routes)
+}
+
+// ListDeadLetters 分页列出死信邮件
+//
+// @Summary 列出死信邮件
+// @Description 按进入死信队列的时间倒序返回重试耗尽仍发送失败的邮件及每次失败的原因，不返回邮件正文
+// @Tags 系统
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "页码" default(1)
+// @Param page_size query int false "每页数量，最大100" default(20)
+// @Success 200 {object} utils.ListResponse{data=[]DeadLetterInfo} "获取成功"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "需要管理员权限"
+// @Failure 500 {object} utils.Response "内部服务器错误"
+// @Router /api/v1/admin/email/dead-letters [get]
+func (h *AdminEmailHandler) ListDeadLetters(c *gin.Context) {
+	page, pageSize := parseDeadLetterPage(c)
+	items, total, err := h.queue.ListDeadLetters(c.Request.Context(), (page-1)*pageSize, pageSize)
+	if err != nil {
+		h.logger.Error("Failed to list email dead letters", zap.Error(err))
+		utils.InternalErrorWithMessage(c, "获取死信邮件失败")
+		return
+	}
+
+	infos := make([]*DeadLetterInfo, len(items))
+	for i, item := range items {
+		infos[i] = toDeadLetterInfo(item)
+	}
+	utils.SuccessList(c, infos, utils.NewPagination(page, pageSize, total))
+}
+
+// RetryDeadLetters 重新发送死信邮件
+//
+// @Summary 重新发送死信邮件
+// @Description 将选中的死信邮件重置重试次数后重新加入发送队列，不存在或已被其他管理员处理的ID被忽略。队列已满时未入队的邮件保留在死信队列中
+// @Tags 系统
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body RetryDeadLettersRequest true "邮件ID，最多500个"
+// @Success 200 {object} utils.Response "重新入队的邮件ID"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "需要管理员权限"
+// @Failure 503 {object} utils.Response "邮件服务未启动或发送队列已满"
+// @Router /api/v1/admin/email/dead-letters/retry [post]
+func (h *AdminEmailHandler) RetryDeadLetters(c *gin.Context) {
+	var req RetryDeadLettersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, fmt.Sprintf("请提供1-%d个邮件ID", maxDeadLetterBatch))
+		return
+	}
+
+	retried, err := h.queue.RetryDeadLetters(c.Request.Context(), req.IDs)
+	h.recordEmailAudit(c, audit.ActionEmailRetry, retried, nil)
+	if err != nil {
+		h.logger.Warn("Failed to retry email dead letters",
+			zap.Int("requested", len(req.IDs)),
+			zap.Int("retried", len(retried)),
+			zap.Error(err))
+		switch {
+		case errors.Is(err, email.ErrServiceNotRunning):
+			utils.ErrorWithMessage(c, utils.CodeServiceUnavailable, "邮件服务未启动")
+		case errors.Is(err, email.ErrQueueFull):
+			utils.ErrorWithData(c, utils.CodeServiceUnavailable, "邮件发送队列已满，请稍后重试", gin.H{"retried": retried})
+		default:
+			utils.InternalErrorWithMessage(c, "重新发送死信邮件失败")
+		}
+		return
+	}
+
+	h.logger.Info("Email dead letters requeued",
+		zap.Int("requested", len(req.IDs)),
+		zap.Int("retried", len(retried)),
+		zap.String("ip", c.ClientIP()))
+	utils.Success(c, gin.H{"retried": retried})
+}
+
+// PurgeDeadLetters 清除死信邮件
+//
+// @Summary 清除死信邮件
+// @Description 删除选中的死信邮件，或在all为true时清空死信队列。删除后无法恢复，需要保留记录时先导出
+// @Tags 系统
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body PurgeDeadLettersRequest true "邮件ID或清空标记"
+// @Success 200 {object} utils.Response "删除的数量"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "需要管理员权限"
+// @Failure 500 {object} utils.Response "内部服务器错误"
+// @Router /api/v1/admin/email/dead-letters/purge [post]
+func (h *AdminEmailHandler) PurgeDeadLetters(c *gin.Context) {
+	var req PurgeDeadLettersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+		return
+	}
+	if req.All {
+		req.IDs = nil
+	} else if len(req.IDs) == 0 {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请选择要清除的邮件，或指定all清空死信队列")
+		return
+	}
+
+	removed, err := h.queue.PurgeDeadLetters(c.Request.Context(), req.IDs)
+	if err != nil {
+		h.logger.Error("Failed to purge email dead letters", zap.Bool("all", req.All), zap.Error(err))
+		utils.InternalErrorWithMessage(c, "清除死信邮件失败")
+		return
+	}
+
+	ids := req.IDs
+	if req.All {
+		ids = []string{"*"}
+	}
+	h.recordEmailAudit(c, audit.ActionEmailPurge, ids, map[string]interface{}{"removed": removed})
+	h.logger.Info("Email dead letters purged",
+		zap.Bool("all", req.All),
+		zap.Int64("removed", removed),
+		zap.String("ip", c.ClientIP()))
+	utils.Success(c, gin.H{"removed": removed})
+}
+
+// ExportDeadLetters 以CSV格式导出全部死信邮件
+//
+// @Summary 导出死信邮件
+// @Description 导出全部死信邮件，列为id,to,subject,template,attempts,max_attempts,created_at,failed_at,last_error,errors。多个收件人以分号分隔，多次失败原因以 | 分隔，不包含邮件正文
+// @Tags 系统
+// @Produce text/csv
+// @Security BearerAuth
+// @Success 200 {file} file "CSV文件"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "需要管理员权限"
+// @Failure 500 {object} utils.Response "内部服务器错误"
+// @Router /api/v1/admin/email/dead-letters/export [get]
+func (h *AdminEmailHandler) ExportDeadLetters(c *gin.Context) {
+	items, err := h.queue.ExportDeadLetters(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to export email dead letters", zap.Error(err))
+		utils.InternalErrorWithMessage(c, "导出死信邮件失败")
+		return
+	}
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	_ = writer.Write([]string{"id", "to", "subject", "template", "attempts", "max_attempts", "created_at", "failed_at", "last_error", "errors"})
+	for _, item := range items {
+		info := toDeadLetterInfo(item)
+		failedAt := ""
+		if info.FailedAt != nil {
+			failedAt = info.FailedAt.Format(time.RFC3339)
+		}
+		_ = writer.Write([]string{
+			info.ID,
+			strings.Join(info.To, ";"),
+			info.Subject,
+			info.Template,
+			strconv.Itoa(info.Attempts),
+			strconv.Itoa(info.MaxAttempts),
+			info.CreatedAt.Format(time.RFC3339),
+			failedAt,
+			info.LastError,
+			strings.Join(info.Errors, " | "),
+		})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		h.logger.Error("Failed to write email dead letter export", zap.Error(err))
+		utils.InternalErrorWithMessage(c, "导出死信邮件失败")
+		return
+	}
+
+	filename := fmt.Sprintf("email-dead-letters_%s.csv", time.Now().Format("20060102-150405"))
+	c.Header("Content-Disposition", contentDisposition(filename))
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Data(200, "text/csv; charset=utf-8", buf.Bytes())
+}
+
+// recordEmailAudit 为每封处理的死信写入一条审计记录
+func (h *AdminEmailHandler) recordEmailAudit(c *gin.Context, action string, ids []string, after map[string]interface{}) {
+	entries := make([]*audit.Entry, 0, len(ids))
+	for _, id := range ids {
+		entries = append(entries, &audit.Entry{
+			Action:     action,
+			TargetType: audit.TargetEmail,
+			TargetID:   id,
+			After:      after,
+		})
+	}
+	recordAdminAudit(c, h.audit, h.logger, entries...)
+}
+
+// toDeadLetterInfo 转换死信邮件信息
+func toDeadLetterInfo(item *email.EmailQueue) *DeadLetterInfo {
+	info := &DeadLetterInfo{
+		ID:          item.ID,
+		To:          item.To,
+		Subject:     item.Subject,
+		Template:    item.Template,
+		Priority:    item.Priority,
+		Attempts:    item.Attempts,
+		MaxAttempts: item.MaxAttempts,
+		LastError:   item.ErrorMsg,
+		Errors:      item.Errors,
+		CreatedAt:   item.CreatedAt,
+		FailedAt:    item.FailedAt,
+	}
+	if info.LastError == "" && len(item.Errors) > 0 {
+		info.LastError = item.Errors[len(item.Errors)-1]
+	}
+	return info
+}
+
+// parseDeadLetterPage 解析分页参数
+func parseDeadLetterPage(c *gin.Context) (int, int) {
+	page, _ := strconv.Atoi(c.Query("page"))
+	if page < 1 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(c.Query("page_size"))
+	if pageSize < 1 {
+		pageSize = defaultDeadLetterPageSize
+	}
+	if pageSize > maxDeadLetterPageSize {
+		pageSize = maxDeadLetterPageSize
+	}
+	return page, pageSize
+}
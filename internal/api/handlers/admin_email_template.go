@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/email"
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/service/audit"
+)
+
+// AdminEmailTemplateHandler 管理员预览和在线修改邮件模板的处理器
+type AdminEmailTemplateHandler struct {
+	manager email.TemplateManager
+	audit   audit.AdminAuditService
+	logger  *zap.Logger
+}
+
+// NewAdminEmailTemplateHandler 创建邮件模板管理处理器
+func NewAdminEmailTemplateHandler(manager email.TemplateManager, logger *zap.Logger) *AdminEmailTemplateHandler {
+	return &AdminEmailTemplateHandler{
+		manager: manager,
+		logger:  logger,
+	}
+}
+
+// SetAuditService 设置管理员操作审计服务，未设置时不记录审计日志
+func (h *AdminEmailTemplateHandler) SetAuditService(service audit.AdminAuditService) {
+	h.audit = service
+}
+
+// PreviewEmailTemplateRequest 预览模板请求
+type PreviewEmailTemplateRequest struct {
+	Template  *email.TemplateDraft   `json:"template"`  // 待预览的模板内容，为空时预览生效的模板
+	Variables map[string]interface{} `json:"variables"` // 渲染变量，为空时使用变量名作为示例值；指定时需包含模板引用的全部变量
+}
+
+// ListTemplates 列出邮件模板
+//
+// @Summary 列出邮件模板
+// @Description 返回全部生效的邮件模板及各语言版本，包括未启用的模板。source表示模板来源：default为内置，file为模板目录，database为管理员修改
+// @Tags 系统
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response{data=[]email.EmailTemplate} "获取成功"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "需要管理员权限"
+// @Router /api/v1/admin/email/templates [get]
+func (h *AdminEmailTemplateHandler) ListTemplates(c *gin.Context) {
+	utils.Success(c, h.manager.ListTemplates())
+}
+
+// GetTemplate 获取邮件模板
+//
+// @Summary 获取邮件模板
+// @Description 返回指定名称和语言的生效模板，variables为模板可以使用的变量及说明
+// @Tags 系统
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "模板名称"
+// @Param language path string true "语言，如 zh-CN、en-US"
+// @Success 200 {object} utils.Response{data=email.EmailTemplate} "获取成功"
+// @Failure 400 {object} utils.Response "不支持的语言"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "需要管理员权限"
+// @Failure 404 {object} utils.Response "模板不存在"
+// @Router /api/v1/admin/email/templates/{name}/{language} [get]
+func (h *AdminEmailTemplateHandler) GetTemplate(c *gin.Context) {
+	tmpl, err := h.manager.GetTemplateVariant(c.Param("name"), c.Param("language"))
+	if err != nil {
+		h.respondTemplateError(c, err, "获取邮件模板失败")
+		return
+	}
+	utils.Success(c, tmpl)
+}
+
+// PreviewTemplate 预览邮件模板
+//
+// @Summary 预览邮件模板
+// @Description 渲染模板但不发送也不保存，用于修改前检查效果。template为空时渲染生效的模板；
+// @Description variables为空时以"[变量名]"作为示例值，指定时缺少模板引用的变量返回错误，与实际发送时的检查一致
+// @Tags 系统
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "模板名称"
+// @Param language path string true "语言，如 zh-CN、en-US"
+// @Param request body PreviewEmailTemplateRequest false "待预览的模板内容和渲染变量"
+// @Success 200 {object} utils.Response{data=email.RenderedEmail} "渲染结果"
+// @Failure 400 {object} utils.Response "模板语法错误、使用了未声明的变量、缺少渲染变量或不支持的语言"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "需要管理员权限"
+// @Failure 404 {object} utils.Response "模板不存在"
+// @Router /api/v1/admin/email/templates/{name}/{language}/preview [post]
+func (h *AdminEmailTemplateHandler) PreviewTemplate(c *gin.Context) {
+	var req PreviewEmailTemplateRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+			return
+		}
+	}
+
+	rendered, err := h.manager.PreviewTemplate(c.Param("name"), c.Param("language"), req.Template, req.Variables)
+	if err != nil {
+		h.respondTemplateError(c, err, "预览邮件模板失败")
+		return
+	}
+	utils.Success(c, rendered)
+}
+
+// UpdateTemplate 修改邮件模板
+//
+// @Summary 修改邮件模板
+// @Description 保存模板并立即生效，多实例部署时其他实例在1分钟内生效。只能修改已有名称的模板，可以新增支持的语言版本；
+// @Description 模板使用Go模板语法，只能引用该模板声明的变量，需要主题和至少一种正文
+// @Tags 系统
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "模板名称"
+// @Param language path string true "语言，如 zh-CN、en-US"
+// @Param request body email.TemplateDraft true "模板内容"
+// @Success 200 {object} utils.Response{data=email.EmailTemplate} "修改后的模板"
+// @Failure 400 {object} utils.Response "模板语法错误、使用了未声明的变量或不支持的语言"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "需要管理员权限"
+// @Failure 404 {object} utils.Response "模板不存在"
+// @Failure 503 {object} utils.Response "未配置模板存储"
+// @Router /api/v1/admin/email/templates/{name}/{language} [put]
+func (h *AdminEmailTemplateHandler) UpdateTemplate(c *gin.Context) {
+	adminID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	var draft email.TemplateDraft
+	if err := c.ShouldBindJSON(&draft); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+		return
+	}
+
+	name, language := c.Param("name"), c.Param("language")
+	before, _ := h.manager.GetTemplateVariant(name, language)
+	tmpl, err := h.manager.UpdateTemplate(c.Request.Context(), name, language, &draft, adminID)
+	if err != nil {
+		h.respondTemplateError(c, err, "修改邮件模板失败")
+		return
+	}
+
+	h.logger.Info("Email template updated",
+		zap.Uint("admin_id", adminID),
+		zap.String("template", tmpl.Name),
+		zap.String("language", tmpl.Language),
+		zap.String("ip", c.ClientIP()))
+	recordAdminAudit(c, h.audit, h.logger, &audit.Entry{
+		Action:     audit.ActionEmailTemplateSave,
+		TargetType: audit.TargetEmailTemplate,
+		TargetID:   tmpl.Name + "/" + tmpl.Language,
+		Before:     emailTemplateSnapshot(before),
+		After:      emailTemplateSnapshot(tmpl),
+	})
+	utils.Success(c, tmpl)
+}
+
+// ResetTemplate 恢复邮件模板
+//
+// @Summary 恢复邮件模板
+// @Description 删除管理员的修改，恢复为模板目录或内置的版本并返回恢复后的模板；该语言版本只存在于修改中时删除该版本，返回的data为空
+// @Tags 系统
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "模板名称"
+// @Param language path string true "语言，如 zh-CN、en-US"
+// @Success 200 {object} utils.Response{data=email.EmailTemplate} "恢复后的模板"
+// @Failure 400 {object} utils.Response "不支持的语言"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "需要管理员权限"
+// @Failure 404 {object} utils.Response "模板未修改过"
+// @Failure 503 {object} utils.Response "未配置模板存储"
+// @Router /api/v1/admin/email/templates/{name}/{language} [delete]
+func (h *AdminEmailTemplateHandler) ResetTemplate(c *gin.Context) {
+	name, language := c.Param("name"), c.Param("language")
+	before, _ := h.manager.GetTemplateVariant(name, language)
+	tmpl, err := h.manager.ResetTemplate(c.Request.Context(), name, language)
+	if err != nil {
+		h.respondTemplateError(c, err, "恢复邮件模板失败")
+		return
+	}
+
+	targetID := name + "/" + language
+	if before != nil {
+		targetID = before.Name + "/" + before.Language
+	}
+	h.logger.Info("Email template reset",
+		zap.String("template", targetID),
+		zap.String("ip", c.ClientIP()))
+	recordAdminAudit(c, h.audit, h.logger, &audit.Entry{
+		Action:     audit.ActionEmailTemplateReset,
+		TargetType: audit.TargetEmailTemplate,
+		TargetID:   targetID,
+		Before:     emailTemplateSnapshot(before),
+		After:      emailTemplateSnapshot(tmpl),
+	})
+	utils.Success(c, tmpl)
+}
+
+// respondTemplateError 按模板管理错误返回响应
+func (h *AdminEmailTemplateHandler) respondTemplateError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, email.ErrTemplateNotFound):
+		utils.ErrorWithMessage(c, utils.CodeNotFound, "邮件模板不存在")
+	case errors.Is(err, email.ErrUnsupportedLanguage):
+		utils.ErrorWithMessage(c, utils.CodeValidationError, "不支持的模板语言")
+	case errors.Is(err, email.ErrInvalidTemplate), errors.Is(err, email.ErrMissingVariables):
+		utils.ErrorWithMessage(c, utils.CodeValidationError, err.Error())
+	case errors.Is(err, email.ErrTemplateStoreUnavailable):
+		utils.ErrorWithMessage(c, utils.CodeServiceUnavailable, "未配置邮件模板存储，不能在线修改模板")
+	default:
+		h.logger.Error("Failed to manage email template", zap.String("operation", message), zap.Error(err))
+		utils.InternalErrorWithMessage(c, message)
+	}
+}
+
+// emailTemplateSnapshot 审计记录中的模板信息，不记录正文
+func emailTemplateSnapshot(tmpl *email.EmailTemplate) map[string]interface{} {
+	if tmpl == nil {
+		return nil
+	}
+	return map[string]interface{}{
+		"subject":   tmpl.Subject,
+		"is_active": tmpl.IsActive,
+		"source":    tmpl.Source,
+	}
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/email"
+	"cloudpan/internal/service/audit"
+)
+
+// stubTemplateManager 记录修改的模板管理接口
+type stubTemplateManager struct {
+	email.TemplateManager
+	templates map[string]*email.EmailTemplate
+	updatedBy uint
+	preview   *email.TemplateDraft
+	updateErr error
+}
+
+func (m *stubTemplateManager) ListTemplates() []*email.EmailTemplate {
+	templates := make([]*email.EmailTemplate, 0, len(m.templates))
+	for _, tmpl := range m.templates {
+		templates = append(templates, tmpl)
+	}
+	return templates
+}
+
+func (m *stubTemplateManager) GetTemplateVariant(name, language string) (*email.EmailTemplate, error) {
+	if language == "fr-FR" {
+		return nil, email.ErrUnsupportedLanguage
+	}
+	tmpl, ok := m.templates[name+"/"+language]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", email.ErrTemplateNotFound, name)
+	}
+	return tmpl, nil
+}
+
+func (m *stubTemplateManager) PreviewTemplate(name, language string, draft *email.TemplateDraft, variables map[string]interface{}) (*email.RenderedEmail, error) {
+	m.preview = draft
+	if _, err := m.GetTemplateVariant(name, language); err != nil {
+		return nil, err
+	}
+	if variables != nil && variables["username"] == nil {
+		return nil, fmt.Errorf("%w: username", email.ErrMissingVariables)
+	}
+	return &email.RenderedEmail{Name: name, Language: language, Subject: "Hi [username]"}, nil
+}
+
+func (m *stubTemplateManager) UpdateTemplate(_ context.Context, name, language string, draft *email.TemplateDraft, updatedBy uint) (*email.EmailTemplate, error) {
+	if m.updateErr != nil {
+		return nil, m.updateErr
+	}
+	m.updatedBy = updatedBy
+	tmpl := &email.EmailTemplate{Name: name, Language: language, Subject: draft.Subject, Source: email.TemplateSourceDatabase, IsActive: true}
+	m.templates[name+"/"+language] = tmpl
+	return tmpl, nil
+}
+
+func (m *stubTemplateManager) ResetTemplate(_ context.Context, name, language string) (*email.EmailTemplate, error) {
+	tmpl, ok := m.templates[name+"/"+language]
+	if !ok || tmpl.Source != email.TemplateSourceDatabase {
+		return nil, fmt.Errorf("%w: not customized", email.ErrTemplateNotFound)
+	}
+	restored := &email.EmailTemplate{Name: name, Language: language, Subject: "Welcome", Source: email.TemplateSourceDefault, IsActive: true}
+	m.templates[name+"/"+language] = restored
+	return restored, nil
+}
+
+func newStubTemplateManager() *stubTemplateManager {
+	return &stubTemplateManager{templates: map[string]*email.EmailTemplate{
+		"welcome/en-US": {Name: "welcome", Language: "en-US", Subject: "Welcome", Source: email.TemplateSourceDefault, IsActive: true},
+	}}
+}
+
+func setupAdminEmailTemplateRouter(manager email.TemplateManager, auditService audit.AdminAuditService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler := NewAdminEmailTemplateHandler(manager, zap.NewNop())
+	handler.SetAuditService(auditService)
+	admin := router.Group("/admin/email", func(c *gin.Context) {
+		c.Set("user_id", uint64(1))
+	})
+	admin.GET("/templates", handler.ListTemplates)
+	admin.GET("/templates/:name/:language", handler.GetTemplate)
+	admin.POST("/templates/:name/:language/preview", handler.PreviewTemplate)
+	admin.PUT("/templates/:name/:language", handler.UpdateTemplate)
+	admin.DELETE("/templates/:name/:language", handler.ResetTemplate)
+	return router
+}
+
+func TestAdminEmailTemplateHandler_GetAndPreview(t *testing.T) {
+	manager := newStubTemplateManager()
+	router := setupAdminEmailTemplateRouter(manager, nil)
+
+	w := serveAdminEmail(router, http.MethodGet, "/admin/email/templates", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"source":"default"`)
+
+	w = serveAdminEmail(router, http.MethodGet, "/admin/email/templates/welcome/en-US", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	w = serveAdminEmail(router, http.MethodGet, "/admin/email/templates/missing/en-US", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = serveAdminEmail(router, http.MethodGet, "/admin/email/templates/welcome/fr-FR", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// 没有请求体时预览生效的模板
+	w = serveAdminEmail(router, http.MethodPost, "/admin/email/templates/welcome/en-US/preview", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Hi [username]")
+	assert.Nil(t, manager.preview)
+
+	w = serveAdminEmail(router, http.MethodPost, "/admin/email/templates/welcome/en-US/preview",
+		`{"template":{"subject":"Hi {{.username}}","text_body":"Hi"},"variables":{"app_name":"CloudPan"}}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "username")
+	require.NotNil(t, manager.preview)
+	assert.Equal(t, "Hi {{.username}}", manager.preview.Subject)
+}
+
+func TestAdminEmailTemplateHandler_UpdateAndReset(t *testing.T) {
+	manager := newStubTemplateManager()
+	recorder := &recordingAuditService{}
+	router := setupAdminEmailTemplateRouter(manager, recorder)
+
+	w := serveAdminEmail(router, http.MethodPut, "/admin/email/templates/welcome/en-US", `{"subject":"Hi {{.username}}","text_body":"Hi"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, uint(1), manager.updatedBy)
+	require.Len(t, recorder.entries, 1)
+	assert.Equal(t, audit.ActionEmailTemplateSave, recorder.entries[0].Action)
+	assert.Equal(t, audit.TargetEmailTemplate, recorder.entries[0].TargetType)
+	assert.Equal(t, "welcome/en-US", recorder.entries[0].TargetID)
+	assert.Equal(t, "Welcome", recorder.entries[0].Before["subject"])
+	assert.Equal(t, email.TemplateSourceDatabase, recorder.entries[0].After["source"])
+
+	w = serveAdminEmail(router, http.MethodDelete, "/admin/email/templates/welcome/en-US", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Len(t, recorder.entries, 2)
+	assert.Equal(t, audit.ActionEmailTemplateReset, recorder.entries[1].Action)
+	assert.Equal(t, email.TemplateSourceDefault, recorder.entries[1].After["source"])
+
+	// 未修改过的模板不能恢复
+	w = serveAdminEmail(router, http.MethodDelete, "/admin/email/templates/welcome/en-US", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Len(t, recorder.entries, 2)
+
+	manager.updateErr = fmt.Errorf("%w: undeclared variables password", email.ErrInvalidTemplate)
+	w = serveAdminEmail(router, http.MethodPut, "/admin/email/templates/welcome/en-US", `{"subject":"{{.password}}","text_body":"Hi"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "undeclared variables password")
+
+	manager.updateErr = email.ErrTemplateStoreUnavailable
+	w = serveAdminEmail(router, http.MethodPut, "/admin/email/templates/welcome/en-US", `{"subject":"Hi","text_body":"Hi"}`)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Len(t, recorder.entries, 2)
+}
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/email"
+	"cloudpan/internal/service/audit"
+)
+
+// stubDeadLetterQueue 记录调用参数的死信队列
+type stubDeadLetterQueue struct {
+	items     []*email.EmailQueue
+	retryErr  error
+	retryIDs  []string
+	purgedIDs []string
+	purged    bool
+}
+
+func (q *stubDeadLetterQueue) ListDeadLetters(_ context.Context, offset, limit int) ([]*email.EmailQueue, int64, error) {
+	items := q.items[offset:]
+	if limit < len(items) {
+		items = items[:limit]
+	}
+	return items, int64(len(q.items)), nil
+}
+
+func (q *stubDeadLetterQueue) RetryDeadLetters(_ context.Context, ids []string) ([]string, error) {
+	q.retryIDs = ids
+	if q.retryErr != nil {
+		return ids[:1], q.retryErr
+	}
+	return ids, nil
+}
+
+func (q *stubDeadLetterQueue) PurgeDeadLetters(_ context.Context, ids []string) (int64, error) {
+	q.purged, q.purgedIDs = true, ids
+	if ids == nil {
+		return int64(len(q.items)), nil
+	}
+	return int64(len(ids)), nil
+}
+
+func (q *stubDeadLetterQueue) ExportDeadLetters(_ context.Context) ([]*email.EmailQueue, error) {
+	return q.items, nil
+}
+
+func (q *stubDeadLetterQueue) DeadLetterStatus(_ context.Context) (*email.DeadLetterStatus, error) {
+	return &email.DeadLetterStatus{DeadLetters: int64(len(q.items))}, nil
+}
+
+func setupAdminEmailRouter(queue email.DeadLetterQueue, auditService audit.AdminAuditService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler := NewAdminEmailHandler(queue, zap.NewNop())
+	handler.SetAuditService(auditService)
+	admin := router.Group("/admin/email", func(c *gin.Context) {
+		c.Set("user_id", uint64(1))
+	})
+	admin.GET("/queue", handler.GetQueueStatus)
+	admin.GET("/dead-letters", handler.ListDeadLetters)
+	admin.GET("/dead-letters/export", handler.ExportDeadLetters)
+	admin.POST("/dead-letters/retry", handler.RetryDeadLetters)
+	admin.POST("/dead-letters/purge", handler.PurgeDeadLetters)
+	return router
+}
+
+// stubRouteMonitor 返回固定统计的发送线路监控
+type stubRouteMonitor []*email.RouteStats
+
+func (m stubRouteMonitor) RouteStats() []*email.RouteStats {
+	return m
+}
+
+func newStubDeadLetterQueue() *stubDeadLetterQueue {
+	failedAt := time.Date(2026, 5, 1, 8, 0, 0, 0, time.UTC)
+	return &stubDeadLetterQueue{items: []*email.EmailQueue{
+		{ID: "e1", To: []string{"a@example.com"}, Template: "password_reset", Attempts: 3, MaxAttempts: 3,
+			Variables: map[string]interface{}{"reset_url": "https://example.com/reset?token=secret"},
+			Errors:    []string{"timeout", "535 auth failed"}, FailedAt: &failedAt},
+		{ID: "e2", To: []string{"b@example.com", "c@example.com"}, Subject: "周报", HTMLBody: "<p>正文</p>",
+			Attempts: 3, MaxAttempts: 3, ErrorMsg: "550 mailbox unavailable", FailedAt: &failedAt},
+	}}
+}
+
+func serveAdminEmail(router *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAdminEmailHandler_ListAndExport(t *testing.T) {
+	router := setupAdminEmailRouter(newStubDeadLetterQueue(), nil)
+
+	w := serveAdminEmail(router, http.MethodGet, "/admin/email/dead-letters?page=1&page_size=1", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.Contains(t, body, `"last_error":"535 auth failed"`)
+	assert.Contains(t, body, `"total_count":2`)
+	assert.NotContains(t, body, "token=secret") // 不返回模板变量
+
+	w = serveAdminEmail(router, http.MethodGet, "/admin/email/dead-letters/export", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "email-dead-letters_")
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, "id,to,subject,template,attempts,max_attempts,created_at,failed_at,last_error,errors", lines[0])
+	assert.Contains(t, lines[1], "timeout | 535 auth failed")
+	assert.Contains(t, lines[2], "b@example.com;c@example.com")
+	assert.NotContains(t, w.Body.String(), "正文")
+
+	w = serveAdminEmail(router, http.MethodGet, "/admin/email/queue", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"dead_letters":2`)
+}
+
+func TestAdminEmailHandler_Retry(t *testing.T) {
+	queue := newStubDeadLetterQueue()
+	recorder := &recordingAuditService{}
+	router := setupAdminEmailRouter(queue, recorder)
+
+	w := serveAdminEmail(router, http.MethodPost, "/admin/email/dead-letters/retry", `{"ids":[]}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = serveAdminEmail(router, http.MethodPost, "/admin/email/dead-letters/retry", `{"ids":["e1","e2"]}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"e1", "e2"}, queue.retryIDs)
+	require.Len(t, recorder.entries, 2)
+	assert.Equal(t, audit.ActionEmailRetry, recorder.entries[0].Action)
+	assert.Equal(t, audit.TargetEmail, recorder.entries[0].TargetType)
+
+	// 队列已满时只为已入队的邮件记录审计
+	queue.retryErr = email.ErrQueueFull
+	recorder.entries = nil
+	w = serveAdminEmail(router, http.MethodPost, "/admin/email/dead-letters/retry", `{"ids":["e1","e2"]}`)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), `"retried":["e1"]`)
+	assert.Len(t, recorder.entries, 1)
+
+	queue.retryErr = email.ErrServiceNotRunning
+	w = serveAdminEmail(router, http.MethodPost, "/admin/email/dead-letters/retry", `{"ids":["e1"]}`)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
+func TestAdminEmailHandler_Purge(t *testing.T) {
+	queue := newStubDeadLetterQueue()
+	recorder := &recordingAuditService{}
+	router := setupAdminEmailRouter(queue, recorder)
+
+	// 未选择邮件也未指定all时拒绝，避免误清空
+	w := serveAdminEmail(router, http.MethodPost, "/admin/email/dead-letters/purge", `{}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.False(t, queue.purged)
+
+	w = serveAdminEmail(router, http.MethodPost, "/admin/email/dead-letters/purge", `{"ids":["e1"]}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"e1"}, queue.purgedIDs)
+
+	recorder.entries = nil
+	w = serveAdminEmail(router, http.MethodPost, "/admin/email/dead-letters/purge", `{"ids":["e1"],"all":true}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Nil(t, queue.purgedIDs)
+	assert.Contains(t, w.Body.String(), `"removed":2`)
+	require.Len(t, recorder.entries, 1)
+	assert.Equal(t, "*", recorder.entries[0].TargetID)
+	assert.Equal(t, audit.ActionEmailPurge, recorder.entries[0].Action)
+}
+
+func TestAdminEmailHandler_ListRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	handler := NewAdminEmailHandler(newStubDeadLetterQueue(), zap.NewNop())
+	router := gin.New()
+	router.GET("/admin/email/routes", handler.ListRoutes)
+
+	w := serveAdminEmail(router, http.MethodGet, "/admin/email/routes", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"data":[]`, "没有配置发送线路时返回空列表")
+
+	until := time.Date(2026, 10, 1, 8, 10, 0, 0, time.UTC)
+	handler.SetRouteMonitor(stubRouteMonitor{
+		{Name: "cn", Domains: []string{"qq.com"}, Failed: 12, WindowFailed: 12, FailureRate: 0.6, Demoted: true, DemotedUntil: &until},
+		{Name: email.DefaultRouteName, Sent: 40},
+	})
+	w = serveAdminEmail(router, http.MethodGet, "/admin/email/routes", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.Contains(t, body, `"name":"cn"`)
+	assert.Contains(t, body, `"demoted":true`)
+	assert.Contains(t, body, `"name":"default"`)
+}
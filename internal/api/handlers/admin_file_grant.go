@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/service/acl"
+	"cloudpan/internal/service/audit"
+)
+
+// AdminFileGrantHandler 管理员管理文件访问授权的处理器
+type AdminFileGrantHandler struct {
+	securityAudit
+	service acl.PermissionService
+	audit   audit.AdminAuditService
+	logger  *zap.Logger
+}
+
+// NewAdminFileGrantHandler 创建文件访问授权管理处理器
+func NewAdminFileGrantHandler(service acl.PermissionService, logger *zap.Logger) *AdminFileGrantHandler {
+	return &AdminFileGrantHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// SetAuditService 设置管理员操作审计服务，未设置时不记录审计日志
+func (h *AdminFileGrantHandler) SetAuditService(service audit.AdminAuditService) {
+	h.audit = service
+}
+
+// ListGrants 列出文件上的授权
+//
+// @Summary 列出文件访问授权
+// @Description 返回直接授予在该文件或文件夹上的授权，不含从上级文件夹继承的授权
+// @Tags 系统
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "文件ID"
+// @Success 200 {object} utils.Response{data=[]acl.GrantInfo} "获取成功"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "需要管理员权限"
+// @Failure 404 {object} utils.Response "文件不存在"
+// @Router /api/v1/admin/files/{id}/grants [get]
+func (h *AdminFileGrantHandler) ListGrants(c *gin.Context) {
+	fileID, ok := parseIDParam(c, "id")
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "文件ID格式错误")
+		return
+	}
+
+	grants, err := h.service.ListGrants(c.Request.Context(), fileID)
+	if err != nil {
+		respondServiceError(c, err, "获取文件授权失败")
+		return
+	}
+	utils.Success(c, grants)
+}
+
+// Grant 授予文件访问权限
+//
+// @Summary 授予文件访问权限
+// @Description 把文件或文件夹的权限授予用户或团队，文件夹上的授权对其下全部文件和子文件夹生效。
+// @Description 权限为 read(查看、下载、浏览)、write(重命名、移动、复制、新建文件夹)、share(创建分享)、delete(移入回收站)，任何授权都包含read。
+// @Description 同一文件对同一用户或团队再次授权时覆盖原有权限；上传始终只能上传到自己的空间
+// @Tags 系统
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "文件ID"
+// @Param request body acl.GrantRequest true "授权对象和权限"
+// @Success 200 {object} utils.Response{data=acl.GrantInfo} "授权成功"
+// @Failure 400 {object} utils.Response "请求参数错误、权限无效或授权对象是文件所有者"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "需要管理员权限"
+// @Failure 404 {object} utils.Response "文件、用户或团队不存在"
+// @Router /api/v1/admin/files/{id}/grants [put]
+func (h *AdminFileGrantHandler) Grant(c *gin.Context) {
+	adminID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	fileID, ok := parseIDParam(c, "id")
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "文件ID格式错误")
+		return
+	}
+
+	var req acl.GrantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+		return
+	}
+
+	// 记录覆盖前的授权，用于审计
+	existing, err := h.service.ListGrants(c.Request.Context(), fileID)
+	if err != nil {
+		respondServiceError(c, err, "授予文件权限失败")
+		return
+	}
+	var before map[string]interface{}
+	for _, grant := range existing {
+		if grant.SubjectType == req.SubjectType && grant.SubjectID == req.SubjectID {
+			before = fileGrantSnapshot(grant)
+			break
+		}
+	}
+
+	grant, err := h.service.Grant(c.Request.Context(), adminID, fileID, &req)
+	if err != nil {
+		respondServiceError(c, err, "授予文件权限失败")
+		return
+	}
+
+	h.logger.Info("File permissions granted",
+		zap.Uint("admin_id", adminID),
+		zap.Uint("file_id", fileID),
+		zap.Uint("grant_id", grant.ID),
+		zap.String("ip", c.ClientIP()))
+	recordAdminAudit(c, h.audit, h.logger, &audit.Entry{
+		Action:     audit.ActionFileGrantChange,
+		TargetType: audit.TargetFile,
+		TargetID:   strconv.FormatUint(uint64(fileID), 10),
+		Before:     before,
+		After:      fileGrantSnapshot(grant),
+	})
+	h.recordSecurity(c, h.logger, &audit.Event{
+		Action:       audit.SecurityActionPermissionGrant,
+		ResourceType: audit.ResourceFile,
+		ResourceID:   strconv.FormatUint(uint64(fileID), 10),
+		Before:       before,
+		After:        fileGrantSnapshot(grant),
+	})
+
+	utils.Success(c, grant)
+}
+
+// RevokeGrant 撤销文件上的授权
+//
+// @Summary 撤销文件访问授权
+// @Tags 系统
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "文件ID"
+// @Param grant_id path int true "授权ID"
+// @Success 200 {object} utils.Response{data=acl.GrantInfo} "撤销成功，返回被撤销的授权"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "需要管理员权限"
+// @Failure 404 {object} utils.Response "授权不存在"
+// @Router /api/v1/admin/files/{id}/grants/{grant_id} [delete]
+func (h *AdminFileGrantHandler) RevokeGrant(c *gin.Context) {
+	fileID, ok := parseIDParam(c, "id")
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "文件ID格式错误")
+		return
+	}
+	grantID, ok := parseIDParam(c, "grant_id")
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "授权ID格式错误")
+		return
+	}
+
+	grant, err := h.service.Revoke(c.Request.Context(), fileID, grantID)
+	if err != nil {
+		respondServiceError(c, err, "撤销文件授权失败")
+		return
+	}
+
+	h.logger.Info("File permissions revoked",
+		zap.Uint("file_id", fileID),
+		zap.Uint("grant_id", grantID),
+		zap.String("ip", c.ClientIP()))
+	recordAdminAudit(c, h.audit, h.logger, &audit.Entry{
+		Action:     audit.ActionFileGrantChange,
+		TargetType: audit.TargetFile,
+		TargetID:   strconv.FormatUint(uint64(fileID), 10),
+		Before:     fileGrantSnapshot(grant),
+	})
+	h.recordSecurity(c, h.logger, &audit.Event{
+		Action:       audit.SecurityActionPermissionRevoke,
+		ResourceType: audit.ResourceFile,
+		ResourceID:   strconv.FormatUint(uint64(fileID), 10),
+		Before:       fileGrantSnapshot(grant),
+	})
+
+	utils.Success(c, grant)
+}
+
+// GetEffectivePermissions 查询用户对文件的有效权限
+//
+// @Summary 查询用户对文件的有效权限
+// @Description 返回用户对文件的有效权限(含从上级文件夹和所在团队继承的授权)及生效的授权ID，用于排查访问问题
+// @Tags 系统
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "文件ID"
+// @Param user_id query int true "用户ID"
+// @Success 200 {object} utils.Response{data=acl.EffectivePermissions} "获取成功"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "需要管理员权限"
+// @Failure 404 {object} utils.Response "文件不存在"
+// @Router /api/v1/admin/files/{id}/permissions [get]
+func (h *AdminFileGrantHandler) GetEffectivePermissions(c *gin.Context) {
+	fileID, ok := parseIDParam(c, "id")
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "文件ID格式错误")
+		return
+	}
+	userID, err := strconv.ParseUint(c.Query("user_id"), 10, 64)
+	if err != nil || userID == 0 {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "用户ID格式错误")
+		return
+	}
+
+	effective, err := h.service.EffectivePermissions(c.Request.Context(), uint(userID), fileID)
+	if err != nil {
+		respondServiceError(c, err, "获取有效权限失败")
+		return
+	}
+	utils.Success(c, effective)
+}
+
+// fileGrantSnapshot 授权的审计快照
+func fileGrantSnapshot(grant *acl.GrantInfo) map[string]interface{} {
+	return map[string]interface{}{
+		"grant_id":     grant.ID,
+		"subject_type": grant.SubjectType,
+		"subject_id":   grant.SubjectID,
+		"permissions":  grant.Permissions,
+	}
+}
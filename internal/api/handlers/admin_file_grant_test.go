@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/service/acl"
+	"cloudpan/internal/service/audit"
+)
+
+// stubPermissionService 记录调用参数的文件权限服务
+type stubPermissionService struct {
+	acl.PermissionService
+	grants  []*acl.GrantInfo
+	granted *acl.GrantRequest
+	adminID uint
+}
+
+func (s *stubPermissionService) ListGrants(_ context.Context, fileID uint) ([]*acl.GrantInfo, error) {
+	if fileID != 3 {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "文件不存在")
+	}
+	return s.grants, nil
+}
+
+func (s *stubPermissionService) Grant(_ context.Context, adminID, fileID uint, req *acl.GrantRequest) (*acl.GrantInfo, error) {
+	s.adminID, s.granted = adminID, req
+	if req.SubjectType != "user" && req.SubjectType != "team" {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "无效的授权对象类型")
+	}
+	return &acl.GrantInfo{ID: 11, FileID: fileID, SubjectType: req.SubjectType, SubjectID: req.SubjectID, Permissions: req.Permissions}, nil
+}
+
+func (s *stubPermissionService) Revoke(_ context.Context, fileID, grantID uint) (*acl.GrantInfo, error) {
+	for _, grant := range s.grants {
+		if grant.ID == grantID && grant.FileID == fileID {
+			return grant, nil
+		}
+	}
+	return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "授权不存在")
+}
+
+func (s *stubPermissionService) EffectivePermissions(_ context.Context, userID, fileID uint) (*acl.EffectivePermissions, error) {
+	return &acl.EffectivePermissions{FileID: fileID, UserID: userID, Permissions: []string{"read"}, SourceIDs: []uint{10}}, nil
+}
+
+func setupAdminFileGrantRouter(service acl.PermissionService, auditService audit.AdminAuditService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler := NewAdminFileGrantHandler(service, zap.NewNop())
+	handler.SetAuditService(auditService)
+	admin := router.Group("/admin/files", func(c *gin.Context) {
+		c.Set("user_id", uint64(1))
+	})
+	admin.GET("/:id/grants", handler.ListGrants)
+	admin.PUT("/:id/grants", handler.Grant)
+	admin.DELETE("/:id/grants/:grant_id", handler.RevokeGrant)
+	admin.GET("/:id/permissions", handler.GetEffectivePermissions)
+	return router
+}
+
+func TestAdminFileGrantHandler_Grant(t *testing.T) {
+	service := &stubPermissionService{grants: []*acl.GrantInfo{
+		{ID: 10, FileID: 3, SubjectType: "user", SubjectID: 2, Permissions: []string{"read"}},
+	}}
+	recorder := &recordingAuditService{}
+	router := setupAdminFileGrantRouter(service, recorder)
+
+	w := serveAdminEmail(router, http.MethodPut, "/admin/files/3/grants", `{"subject_type":"user","subject_id":2,"permissions":["read","write"]}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, uint(1), service.adminID)
+	assert.Equal(t, []string{"read", "write"}, service.granted.Permissions)
+	require.Len(t, recorder.entries, 1)
+	entry := recorder.entries[0]
+	assert.Equal(t, audit.ActionFileGrantChange, entry.Action)
+	assert.Equal(t, audit.TargetFile, entry.TargetType)
+	assert.Equal(t, "3", entry.TargetID)
+	assert.Equal(t, []string{"read"}, entry.Before["permissions"]) // 覆盖前的授权
+	assert.Equal(t, []string{"read", "write"}, entry.After["permissions"])
+
+	w = serveAdminEmail(router, http.MethodPut, "/admin/files/3/grants", `{"subject_type":"group","subject_id":2,"permissions":["read"]}`)
+	assert.Equal(t, utils.CodeValidationError, decodeShareResponse(t, w).Code)
+
+	w = serveAdminEmail(router, http.MethodPut, "/admin/files/4/grants", `{"subject_type":"user","subject_id":2,"permissions":["read"]}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = serveAdminEmail(router, http.MethodPut, "/admin/files/3/grants", `{"subject_type":"user"}`)
+	assert.Equal(t, utils.CodeBadRequest, decodeShareResponse(t, w).Code)
+}
+
+func TestAdminFileGrantHandler_RevokeAndInspect(t *testing.T) {
+	service := &stubPermissionService{grants: []*acl.GrantInfo{
+		{ID: 10, FileID: 3, SubjectType: "team", SubjectID: 5, Permissions: []string{"read", "share"}},
+	}}
+	recorder := &recordingAuditService{}
+	router := setupAdminFileGrantRouter(service, recorder)
+
+	w := serveAdminEmail(router, http.MethodDelete, "/admin/files/4/grants/10", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Empty(t, recorder.entries)
+
+	w = serveAdminEmail(router, http.MethodDelete, "/admin/files/3/grants/10", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Len(t, recorder.entries, 1)
+	assert.Nil(t, recorder.entries[0].After)
+	assert.Equal(t, "team", recorder.entries[0].Before["subject_type"])
+
+	w = serveAdminEmail(router, http.MethodGet, "/admin/files/3/permissions", "")
+	assert.Equal(t, utils.CodeBadRequest, decodeShareResponse(t, w).Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/files/3/permissions?user_id=2", strings.NewReader("")))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"source_ids":[10]`)
+}
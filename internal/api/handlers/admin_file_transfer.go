@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+	"cloudpan/internal/service/audit"
+	"cloudpan/internal/service/file"
+)
+
+// AdminFileTransferHandler 管理员强制转移文件所有权的处理器
+type AdminFileTransferHandler struct {
+	securityAudit
+	service file.TransferService
+	audit   audit.AdminAuditService
+	logger  *zap.Logger
+}
+
+// NewAdminFileTransferHandler 创建强制转移处理器
+func NewAdminFileTransferHandler(service file.TransferService, logger *zap.Logger) *AdminFileTransferHandler {
+	return &AdminFileTransferHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// SetAuditService 设置管理员操作审计服务，未设置时不记录审计日志
+func (h *AdminFileTransferHandler) SetAuditService(service audit.AdminAuditService) {
+	h.audit = service
+}
+
+// ForceTransfer 强制转移文件所有权
+//
+// @Summary 强制转移文件所有权
+// @Description 用于用户离职交接，不需要接收方接受，不检查接收方的存储配额。指定 file_id 时把该文件或文件夹转到接收方根目录；
+// @Description 未指定时在接收方根目录下创建"<原所有者用户名> 的文件"文件夹，把原所有者根目录下的全部文件转入，
+// @Description 单个文件失败不影响其他文件，失败的转移保持已接受状态，可以通过完成接口重试
+// @Tags 系统
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body file.ForceTransferRequest true "原所有者、接收方和原因"
+// @Success 200 {object} utils.Response{data=file.ForceTransferResult} "转移结果"
+// @Failure 400 {object} utils.Response "请求参数错误或文件不属于原所有者"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "需要管理员权限、接收方账户不可用或文件当前不可转移"
+// @Failure 404 {object} utils.Response "文件、原所有者或接收方不存在"
+// @Failure 409 {object} utils.Response "文件或其所在文件夹正在转移中"
+// @Router /api/v1/admin/transfers [post]
+func (h *AdminFileTransferHandler) ForceTransfer(c *gin.Context) {
+	adminID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	var req file.ForceTransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+		return
+	}
+
+	result, err := h.service.ForceTransfer(c.Request.Context(), adminID, &req)
+	if err != nil {
+		respondServiceError(c, err, "强制转移失败")
+		return
+	}
+
+	h.logger.Info("Files force transferred",
+		zap.Uint("admin_id", adminID),
+		zap.Uint("from_user_id", req.FromUserID),
+		zap.Uint("to_user_id", req.ToUserID),
+		zap.Int("completed", result.Completed),
+		zap.Int("failed", result.Failed),
+		zap.String("ip", c.ClientIP()))
+	fileIDs := make([]uint, 0, len(result.Transfers))
+	var events []*audit.Event
+	for _, transfer := range result.Transfers {
+		fileIDs = append(fileIDs, transfer.FileID)
+		if transfer.Status == models.TransferStatusCompleted {
+			events = append(events, fileOwnerChangeEvent(transfer))
+		}
+	}
+	recordAdminAudit(c, h.audit, h.logger, &audit.Entry{
+		Action:     audit.ActionFileTransferForce,
+		TargetType: audit.TargetUser,
+		TargetID:   strconv.FormatUint(uint64(req.FromUserID), 10),
+		Before:     map[string]interface{}{"owner_id": req.FromUserID},
+		After: map[string]interface{}{
+			"to_user_id": req.ToUserID,
+			"file_ids":   fileIDs,
+			"completed":  result.Completed,
+			"failed":     result.Failed,
+		},
+		Reason: req.Reason,
+	})
+	h.recordSecurity(c, h.logger, events...)
+	utils.Success(c, result)
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/service/audit"
+)
+
+func TestAdminFileTransferHandler_ForceTransfer(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	service := &stubTransferService{}
+	recorder := &recordingAuditService{}
+	security := &recordingSecurityAudit{}
+	handler := NewAdminFileTransferHandler(service, zap.NewNop())
+	handler.SetAuditService(recorder)
+	handler.SetSecurityAuditService(security)
+	router := gin.New()
+	router.POST("/admin/transfers", func(c *gin.Context) {
+		c.Set("user_id", uint64(1))
+	}, handler.ForceTransfer)
+
+	w := serveAdminEmail(router, http.MethodPost, "/admin/transfers", `{"from_user_id":7,"to_user_id":8}`)
+	assert.Equal(t, utils.CodeBadRequest, decodeShareResponse(t, w).Code)
+	assert.Empty(t, recorder.entries)
+
+	w = serveAdminEmail(router, http.MethodPost, "/admin/transfers", `{"from_user_id":7,"to_user_id":8,"reason":"离职交接"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "离职交接", service.forced.Reason)
+
+	require.Len(t, recorder.entries, 1)
+	entry := recorder.entries[0]
+	assert.Equal(t, audit.ActionFileTransferForce, entry.Action)
+	assert.Equal(t, audit.TargetUser, entry.TargetType)
+	assert.Equal(t, "7", entry.TargetID)
+	assert.Equal(t, "离职交接", entry.Reason)
+	assert.Equal(t, []uint{1, 5}, entry.After["file_ids"])
+	assert.Equal(t, 1, entry.After["failed"])
+
+	// 只为已完成的转移记录所有者变更
+	require.Len(t, security.events, 1)
+	assert.Equal(t, audit.SecurityActionOwnerChange, security.events[0].Action)
+	assert.Equal(t, "1", security.events[0].ResourceID)
+}
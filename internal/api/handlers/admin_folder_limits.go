@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/utils"
+	filesvc "cloudpan/internal/service/file"
+)
+
+// AdminFolderLimitHandler 管理员查看接近文件夹限制的文件夹的处理器
+type AdminFolderLimitHandler struct {
+	limiter filesvc.FolderLimiter
+	logger  *zap.Logger
+}
+
+// NewAdminFolderLimitHandler 创建文件夹限制报告处理器
+func NewAdminFolderLimitHandler(limiter filesvc.FolderLimiter, logger *zap.Logger) *AdminFolderLimitHandler {
+	return &AdminFolderLimitHandler{
+		limiter: limiter,
+		logger:  logger,
+	}
+}
+
+// GetFolderLimitReport 查询层级或子项数接近上限的文件夹
+//
+// @Summary 查询接近限制的文件夹
+// @Description 列出嵌套层级或直接子项数达到上限一定比例的文件夹(含用户根目录)，返回生效的上限；限制生效前已超过上限的文件夹占用比例大于1
+// @Tags 系统
+// @Produce json
+// @Security BearerAuth
+// @Param ratio query number false "达到上限的比例(0,1]，默认使用配置的storage.folders.warn_ratio"
+// @Param limit query int false "每类文件夹的最大条数，默认100，最大1000"
+// @Success 200 {object} utils.Response{data=filesvc.FolderLimitReport} "查询成功"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "需要管理员权限"
+// @Failure 500 {object} utils.Response "内部服务器错误"
+// @Router /api/v1/admin/folders/limits [get]
+func (h *AdminFolderLimitHandler) GetFolderLimitReport(c *gin.Context) {
+	var ratio float64
+	if raw := c.Query("ratio"); raw != "" {
+		parsed, err := strconv.ParseFloat(raw, 64)
+		if err != nil || parsed <= 0 || parsed > 1 {
+			utils.ErrorWithMessage(c, utils.CodeBadRequest, "比例必须在0到1之间")
+			return
+		}
+		ratio = parsed
+	}
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	if limit < 1 {
+		limit = filesvc.DefaultFolderReportLimit
+	}
+	limit = min(limit, filesvc.MaxFolderReportLimit)
+
+	report, err := h.limiter.Report(c.Request.Context(), ratio, limit)
+	if err != nil {
+		h.logger.Error("Failed to build folder limit report", zap.Error(err))
+		respondServiceError(c, err, "查询接近限制的文件夹失败")
+		return
+	}
+
+	utils.Success(c, report)
+}
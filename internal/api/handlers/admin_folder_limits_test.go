@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	filesvc "cloudpan/internal/service/file"
+)
+
+// stubFolderLimiter 记录报告参数的文件夹限制
+type stubFolderLimiter struct {
+	filesvc.FolderLimiter
+	ratio float64
+	limit int
+}
+
+func (s *stubFolderLimiter) Report(_ context.Context, ratio float64, limit int) (*filesvc.FolderLimitReport, error) {
+	s.ratio, s.limit = ratio, limit
+	folderID := uint(3)
+	return &filesvc.FolderLimitReport{
+		MaxDepth:    64,
+		MaxChildren: 100,
+		Ratio:       0.8,
+		DeepFolders: []filesvc.FolderUsage{},
+		CrowdedFolders: []filesvc.FolderUsage{
+			{UserID: 7, FolderID: &folderID, Path: "/photos", Children: 95, Usage: 0.95},
+		},
+	}, nil
+}
+
+func setupAdminFolderLimitRouter(limiter filesvc.FolderLimiter) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler := NewAdminFolderLimitHandler(limiter, zap.NewNop())
+	router.GET("/admin/folders/limits", handler.GetFolderLimitReport)
+	return router
+}
+
+func TestAdminFolderLimitHandler_GetFolderLimitReport(t *testing.T) {
+	limiter := &stubFolderLimiter{}
+	router := setupAdminFolderLimitRouter(limiter)
+
+	t.Run("默认参数", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/folders/limits", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, 0.0, limiter.ratio, "未指定比例时使用配置")
+		assert.Equal(t, filesvc.DefaultFolderReportLimit, limiter.limit)
+
+		var resp struct {
+			Data filesvc.FolderLimitReport `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.Len(t, resp.Data.CrowdedFolders, 1)
+		assert.Equal(t, "/photos", resp.Data.CrowdedFolders[0].Path)
+		assert.Equal(t, int64(95), resp.Data.CrowdedFolders[0].Children)
+	})
+
+	t.Run("指定比例和条数", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/folders/limits?ratio=0.5&limit=5000", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, 0.5, limiter.ratio)
+		assert.Equal(t, filesvc.MaxFolderReportLimit, limiter.limit)
+	})
+
+	t.Run("比例超出范围", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/folders/limits?ratio=1.5", nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
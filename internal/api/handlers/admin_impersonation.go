@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+	"cloudpan/internal/service/audit"
+)
+
+const (
+	defaultImpersonationTTL = 15 * time.Minute // 默认模拟登录有效期
+	maxImpersonationTTL     = time.Hour        // 默认模拟登录最长有效期
+)
+
+// ImpersonationUserStore 模拟登录查询目标用户的存储
+type ImpersonationUserStore interface {
+	GetByID(ctx context.Context, id uint) (*models.User, error)
+}
+
+// ImpersonationOptions 模拟登录选项
+type ImpersonationOptions struct {
+	DefaultTTL    time.Duration // 未指定时长时的有效期，为0时使用15分钟
+	MaxTTL        time.Duration // 最长有效期，为0时使用1小时
+	ForceReadOnly bool          // 强制只读模式
+}
+
+// AdminImpersonationHandler 管理员模拟用户登录处理器
+//
+// 模拟登录令牌携带管理员ID，有效期短且不可刷新；认证中间件据此返回模拟登录响应头，
+// 只读模式下拒绝修改数据的请求，并禁止修改密码、邮箱等敏感操作
+type AdminImpersonationHandler struct {
+	users      ImpersonationUserStore
+	jwtManager utils.JWTManager
+	options    ImpersonationOptions
+	audit      audit.AdminAuditService
+	logger     *zap.Logger
+	now        func() time.Time
+}
+
+// NewAdminImpersonationHandler 创建管理员模拟用户登录处理器
+func NewAdminImpersonationHandler(users ImpersonationUserStore, secretKey string, options ImpersonationOptions, logger *zap.Logger) (*AdminImpersonationHandler, error) {
+	jwtManager, err := utils.NewDefaultJWTManager(secretKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JWT manager: %w", err)
+	}
+
+	if options.DefaultTTL <= 0 {
+		options.DefaultTTL = defaultImpersonationTTL
+	}
+	if options.MaxTTL <= 0 {
+		options.MaxTTL = maxImpersonationTTL
+	}
+	if options.DefaultTTL > options.MaxTTL {
+		options.DefaultTTL = options.MaxTTL
+	}
+
+	return &AdminImpersonationHandler{
+		users:      users,
+		jwtManager: jwtManager,
+		options:    options,
+		logger:     logger,
+		now:        time.Now,
+	}, nil
+}
+
+// SetAuditService 设置管理员操作审计服务，未设置时不记录审计日志
+func (h *AdminImpersonationHandler) SetAuditService(service audit.AdminAuditService) {
+	h.audit = service
+}
+
+// ImpersonateUserRequest 模拟用户登录请求
+type ImpersonateUserRequest struct {
+	Reason          string `json:"reason" binding:"required,max=500"`          // 模拟登录原因，记录在审计日志中
+	ReadOnly        bool   `json:"read_only"`                                  // 是否只读模式
+	DurationMinutes int    `json:"duration_minutes" binding:"omitempty,min=1"` // 有效期(分钟)，为空使用默认值，超过上限时按上限签发
+}
+
+// ImpersonationToken 模拟登录令牌
+type ImpersonationToken struct {
+	AccessToken string    `json:"access_token"` // 访问令牌，不可刷新
+	TokenType   string    `json:"token_type"`   // 令牌类型
+	ExpiresIn   int64     `json:"expires_in"`   // 有效期(秒)
+	ExpiresAt   time.Time `json:"expires_at"`   // 过期时间
+	UserID      uint      `json:"user_id"`      // 被模拟的用户ID
+	ReadOnly    bool      `json:"read_only"`    // 是否只读模式
+}
+
+// ImpersonateUser 模拟用户登录
+//
+// @Summary 模拟用户登录
+// @Description 管理员排查问题时以用户身份登录，签发短期且不可刷新的访问令牌。使用该令牌的响应带有 X-Impersonated-By 和 X-Impersonation-Expires 响应头；只读模式下只允许查询请求；修改密码、资料、两步验证和会话等敏感操作一律禁止
+// @Tags 系统
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "用户ID"
+// @Param request body ImpersonateUserRequest true "原因、只读模式和有效期"
+// @Success 200 {object} utils.Response{data=ImpersonationToken} "令牌已签发"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "需要管理员权限、不能模拟自己或用户不可登录"
+// @Failure 404 {object} utils.Response "用户不存在"
+// @Router /api/v1/admin/users/{id}/impersonate [post]
+func (h *AdminImpersonationHandler) ImpersonateUser(c *gin.Context) {
+	adminID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	// 模拟登录令牌不能再发起模拟登录
+	if _, impersonated := c.Get("impersonator_id"); impersonated {
+		utils.ErrorWithMessage(c, utils.CodeForbidden, "模拟登录时不能执行此操作")
+		return
+	}
+
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || userID == 0 {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "用户ID格式错误")
+		return
+	}
+
+	var req ImpersonateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+		return
+	}
+
+	if uint(userID) == adminID {
+		utils.ErrorWithMessage(c, utils.CodeForbidden, "不能模拟自己登录")
+		return
+	}
+
+	target, err := h.users.GetByID(c.Request.Context(), uint(userID))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ErrorWithMessage(c, utils.CodeNotFound, "用户不存在")
+			return
+		}
+		respondServiceError(c, err, "查询用户失败")
+		return
+	}
+	if !target.IsActive() {
+		respondServiceError(c, pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "用户当前状态不能登录"), "模拟登录失败")
+		return
+	}
+
+	ttl := h.options.DefaultTTL
+	if req.DurationMinutes > 0 {
+		ttl = time.Duration(req.DurationMinutes) * time.Minute
+	}
+	if ttl > h.options.MaxTTL {
+		ttl = h.options.MaxTTL
+	}
+	readOnly := req.ReadOnly || h.options.ForceReadOnly
+
+	// 模拟登录始终使用普通用户角色，不继承管理员权限
+	accessToken, err := h.jwtManager.GenerateImpersonationToken(uint64(target.ID), target.Username, target.Email, "user", uint64(adminID), readOnly, ttl)
+	if err != nil {
+		h.logger.Error("Failed to generate impersonation token",
+			zap.Uint("admin_id", adminID),
+			zap.Uint("user_id", target.ID),
+			zap.Error(err))
+		utils.InternalErrorWithMessage(c, "签发模拟登录令牌失败")
+		return
+	}
+
+	expiresAt := h.now().Add(ttl)
+	jti := ""
+	if claims, err := h.jwtManager.ValidateToken(accessToken); err == nil {
+		jti = claims.ID
+		if claims.ExpiresAt != nil {
+			expiresAt = claims.ExpiresAt.Time
+		}
+	}
+
+	h.logger.Warn("Admin impersonation started",
+		zap.Uint("admin_id", adminID),
+		zap.Uint("user_id", target.ID),
+		zap.Bool("read_only", readOnly),
+		zap.Duration("ttl", ttl),
+		zap.String("jti", jti),
+		zap.String("ip", c.ClientIP()))
+
+	recordAdminAudit(c, h.audit, h.logger, &audit.Entry{
+		Action:     audit.ActionUserImpersonate,
+		TargetType: audit.TargetUser,
+		TargetID:   strconv.FormatUint(uint64(target.ID), 10),
+		After: map[string]interface{}{
+			"read_only":  readOnly,
+			"expires_at": expiresAt.UTC().Format(time.RFC3339),
+			"jti":        jti,
+		},
+		Reason: req.Reason,
+	})
+
+	utils.Success(c, ImpersonationToken{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(ttl.Seconds()),
+		ExpiresAt:   expiresAt,
+		UserID:      target.ID,
+		ReadOnly:    readOnly,
+	})
+}
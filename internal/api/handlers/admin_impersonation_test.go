@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+	"cloudpan/internal/service/audit"
+)
+
+// mapImpersonationUserStore 按ID查询用户的内存存储
+type mapImpersonationUserStore map[uint]*models.User
+
+func (s mapImpersonationUserStore) GetByID(_ context.Context, id uint) (*models.User, error) {
+	u, ok := s[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return u, nil
+}
+
+func setupAdminImpersonationRouter(t *testing.T, options ImpersonationOptions) (*gin.Engine, *recordingAuditService) {
+	gin.SetMode(gin.TestMode)
+	users := mapImpersonationUserStore{}
+	for id, status := range map[uint]string{1: "active", 7: "active", 8: "suspended"} {
+		u := &models.User{Username: "user", Email: "user@example.com", Status: status}
+		u.ID = id
+		users[id] = u
+	}
+	handler, err := NewAdminImpersonationHandler(users, testJWTSecret, options, zap.NewNop())
+	require.NoError(t, err)
+	auditService := &recordingAuditService{}
+	handler.SetAuditService(auditService)
+
+	router := gin.New()
+	router.POST("/admin/users/:id/impersonate", func(c *gin.Context) {
+		c.Set("user_id", uint64(1))
+	}, handler.ImpersonateUser)
+	return router, auditService
+}
+
+func TestAdminImpersonationHandler_ImpersonateUser(t *testing.T) {
+	impersonate := func(router *gin.Engine, path string, body interface{}) (*httptest.ResponseRecorder, utils.Response) {
+		reqBody, _ := json.Marshal(body)
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBuffer(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w, decodeShareResponse(t, w)
+	}
+
+	t.Run("签发只读模拟登录令牌并记录审计", func(t *testing.T) {
+		router, auditService := setupAdminImpersonationRouter(t, ImpersonationOptions{MaxTTL: 30 * time.Minute})
+		w, resp := impersonate(router, "/admin/users/7/impersonate", ImpersonateUserRequest{Reason: "排查上传失败", ReadOnly: true, DurationMinutes: 120})
+		require.Equal(t, http.StatusOK, w.Code)
+
+		data, ok := resp.Data.(map[string]interface{})
+		require.True(t, ok)
+		assert.Equal(t, true, data["read_only"])
+		// 超过上限时按上限签发
+		assert.Equal(t, float64(1800), data["expires_in"])
+
+		jwtManager, err := utils.NewDefaultJWTManager(testJWTSecret)
+		require.NoError(t, err)
+		claims, err := jwtManager.ValidateToken(data["access_token"].(string))
+		require.NoError(t, err)
+		assert.Equal(t, uint64(7), claims.UserID)
+		assert.Equal(t, uint64(1), claims.ImpersonatorID)
+		assert.True(t, claims.ReadOnly)
+		assert.Equal(t, "user", claims.Role)
+
+		require.Len(t, auditService.entries, 1)
+		entry := auditService.entries[0]
+		assert.Equal(t, audit.ActionUserImpersonate, entry.Action)
+		assert.Equal(t, "7", entry.TargetID)
+		assert.Equal(t, "排查上传失败", entry.Reason)
+		assert.Equal(t, claims.ID, entry.After["jti"])
+	})
+
+	t.Run("强制只读模式", func(t *testing.T) {
+		router, _ := setupAdminImpersonationRouter(t, ImpersonationOptions{ForceReadOnly: true})
+		w, resp := impersonate(router, "/admin/users/7/impersonate", ImpersonateUserRequest{Reason: "排查"})
+		require.Equal(t, http.StatusOK, w.Code)
+		data := resp.Data.(map[string]interface{})
+		assert.Equal(t, true, data["read_only"])
+		assert.Equal(t, float64(15*60), data["expires_in"])
+	})
+
+	t.Run("拒绝的请求", func(t *testing.T) {
+		router, auditService := setupAdminImpersonationRouter(t, ImpersonationOptions{})
+
+		w, _ := impersonate(router, "/admin/users/7/impersonate", gin.H{})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		w, _ = impersonate(router, "/admin/users/1/impersonate", ImpersonateUserRequest{Reason: "排查"})
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		w, _ = impersonate(router, "/admin/users/8/impersonate", ImpersonateUserRequest{Reason: "排查"})
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		w, _ = impersonate(router, "/admin/users/99/impersonate", ImpersonateUserRequest{Reason: "排查"})
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Empty(t, auditService.entries)
+	})
+}
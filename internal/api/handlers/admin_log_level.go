@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/service/audit"
+)
+
+// LogLevelSwitch 运行时切换应用日志级别，由 logger.LevelSwitch 实现
+type LogLevelSwitch interface {
+	Level() string
+	SetLevel(level string) error
+	SettableLevels() []string
+}
+
+// AdminLogLevelHandler 管理员查看和修改应用日志级别的处理器
+type AdminLogLevelHandler struct {
+	levels LogLevelSwitch
+	audit  audit.AdminAuditService
+	logger *zap.Logger
+}
+
+// NewAdminLogLevelHandler 创建日志级别管理处理器
+func NewAdminLogLevelHandler(levels LogLevelSwitch, logger *zap.Logger) *AdminLogLevelHandler {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &AdminLogLevelHandler{
+		levels: levels,
+		logger: logger,
+	}
+}
+
+// SetAuditService 设置管理员操作审计服务，未设置时不记录审计日志
+func (h *AdminLogLevelHandler) SetAuditService(service audit.AdminAuditService) {
+	h.audit = service
+}
+
+// LogLevelInfo 日志级别信息
+type LogLevelInfo struct {
+	Level     string   `json:"level"`     // 当前级别
+	Available []string `json:"available"` // 允许设置的级别
+}
+
+// UpdateLogLevelRequest 修改日志级别请求
+type UpdateLogLevelRequest struct {
+	Level  string `json:"level" binding:"required"` // 目标级别：debug、info、warn、error
+	Reason string `json:"reason" binding:"max=500"` // 修改原因，写入审计日志
+}
+
+// GetLogLevel 查询应用日志级别
+//
+// @Summary 查询应用日志级别
+// @Description 返回处理本请求的实例当前的应用日志级别和允许设置的级别
+// @Tags 系统
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response{data=LogLevelInfo} "获取成功"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "需要管理员权限"
+// @Router /api/v1/admin/system/log-level [get]
+func (h *AdminLogLevelHandler) GetLogLevel(c *gin.Context) {
+	utils.Success(c, h.info())
+}
+
+// UpdateLogLevel 修改应用日志级别
+//
+// @Summary 修改应用日志级别
+// @Description 运行时修改处理本请求的实例的应用日志级别，立即生效，不影响访问日志。修改只在当前进程内有效，重启后恢复为配置文件中的级别；多实例部署时需对每个实例分别修改
+// @Tags 系统
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body UpdateLogLevelRequest true "目标级别"
+// @Success 200 {object} utils.Response{data=LogLevelInfo} "修改成功"
+// @Failure 400 {object} utils.Response "请求参数错误或不支持的日志级别"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "需要管理员权限"
+// @Router /api/v1/admin/system/log-level [put]
+func (h *AdminLogLevelHandler) UpdateLogLevel(c *gin.Context) {
+	var req UpdateLogLevelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+		return
+	}
+
+	previous := h.levels.Level()
+	if err := h.levels.SetLevel(strings.TrimSpace(req.Level)); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeValidationError,
+			"不支持的日志级别，可选值: "+strings.Join(h.levels.SettableLevels(), ", "))
+		return
+	}
+	current := h.levels.Level()
+
+	// 使用Warn级别，调高到error时仍能在日志中看到修改记录
+	h.logger.Warn("Application log level changed",
+		zap.String("from", previous),
+		zap.String("to", current),
+		zap.String("ip", c.ClientIP()))
+	recordAdminAudit(c, h.audit, h.logger, &audit.Entry{
+		Action:     audit.ActionLogLevelChange,
+		TargetType: audit.TargetSystem,
+		TargetID:   "log_level",
+		Before:     map[string]interface{}{"level": previous},
+		After:      map[string]interface{}{"level": current},
+		Reason:     req.Reason,
+	})
+
+	utils.Success(c, h.info())
+}
+
+// info 返回当前日志级别信息
+func (h *AdminLogLevelHandler) info() LogLevelInfo {
+	return LogLevelInfo{
+		Level:     h.levels.Level(),
+		Available: h.levels.SettableLevels(),
+	}
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/logger"
+	"cloudpan/internal/service/audit"
+)
+
+func setupAdminLogLevelRouter(levels LogLevelSwitch, auditService audit.AdminAuditService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler := NewAdminLogLevelHandler(levels, zap.NewNop())
+	handler.SetAuditService(auditService)
+	admin := router.Group("/admin/system", func(c *gin.Context) {
+		c.Set("user_id", uint64(1))
+	})
+	admin.GET("/log-level", handler.GetLogLevel)
+	admin.PUT("/log-level", handler.UpdateLogLevel)
+	return router
+}
+
+func TestAdminLogLevelHandler(t *testing.T) {
+	levels := logger.NewLevelSwitch()
+	recorder := &recordingAuditService{}
+	router := setupAdminLogLevelRouter(levels, recorder)
+
+	t.Run("查询当前级别", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/system/log-level", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp struct {
+			Data LogLevelInfo `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "info", resp.Data.Level)
+		assert.Equal(t, []string{"debug", "info", "warn", "error"}, resp.Data.Available)
+	})
+
+	t.Run("修改级别并记录审计日志", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/admin/system/log-level",
+			strings.NewReader(`{"level":"debug","reason":"排查上传失败"}`)))
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "debug", levels.Level())
+
+		require.Len(t, recorder.entries, 1)
+		entry := recorder.entries[0]
+		assert.Equal(t, audit.ActionLogLevelChange, entry.Action)
+		assert.Equal(t, audit.TargetSystem, entry.TargetType)
+		assert.Equal(t, "info", entry.Before["level"])
+		assert.Equal(t, "debug", entry.After["level"])
+		assert.Equal(t, "排查上传失败", entry.Reason)
+	})
+
+	t.Run("拒绝不支持的级别", func(t *testing.T) {
+		for _, level := range []string{"fatal", "verbose"} {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/admin/system/log-level",
+				strings.NewReader(`{"level":"`+level+`"}`)))
+			assert.Equal(t, http.StatusBadRequest, w.Code, level)
+		}
+		assert.Equal(t, "debug", levels.Level())
+	})
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/service/audit"
+	"cloudpan/internal/service/user"
+)
+
+// AdminPasswordResetHandler 管理员批量强制重置密码处理器
+type AdminPasswordResetHandler struct {
+	service user.PasswordResetService
+	audit   audit.AdminAuditService
+	logger  *zap.Logger
+}
+
+// NewAdminPasswordResetHandler 创建管理员批量强制重置密码处理器
+func NewAdminPasswordResetHandler(service user.PasswordResetService, logger *zap.Logger) *AdminPasswordResetHandler {
+	return &AdminPasswordResetHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// SetAuditService 设置管理员操作审计服务，未设置时不记录审计日志
+func (h *AdminPasswordResetHandler) SetAuditService(service audit.AdminAuditService) {
+	h.audit = service
+}
+
+// BulkPasswordResetRequest 批量强制重置密码请求
+type BulkPasswordResetRequest struct {
+	UserIDs []uint `json:"user_ids" binding:"required"` // 需要重置密码的用户ID
+	Reason  string `json:"reason" binding:"max=500"`    // 重置原因，显示在重置邮件中
+}
+
+// StartBulkPasswordReset 批量强制重置密码
+//
+// @Summary 批量强制重置密码
+// @Description 安全事件后强制一批用户重置密码：标记用户必须重置密码(标记期间禁止登录)、吊销已签发的全部令牌，并分批发送重置邮件。任务在后台执行，通过任务ID查询进度
+// @Tags 系统
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body BulkPasswordResetRequest true "用户ID列表和原因"
+// @Success 200 {object} utils.Response{data=user.PasswordResetJob} "任务已创建"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "需要管理员权限"
+// @Router /api/v1/admin/users/password-resets [post]
+func (h *AdminPasswordResetHandler) StartBulkPasswordReset(c *gin.Context) {
+	adminID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	var req BulkPasswordResetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+		return
+	}
+
+	job, err := h.service.StartBulkReset(c.Request.Context(), adminID, user.BulkPasswordResetRequest{
+		UserIDs: req.UserIDs,
+		Reason:  req.Reason,
+	})
+	if err != nil {
+		respondServiceError(c, err, "创建重置任务失败")
+		return
+	}
+
+	h.logger.Warn("Bulk password reset requested",
+		zap.Uint("admin_id", adminID),
+		zap.String("job_id", job.ID),
+		zap.Int("total", job.Total),
+		zap.String("ip", c.ClientIP()))
+
+	// 每个用户一条审计记录，便于按用户查询
+	seen := make(map[uint]bool, len(req.UserIDs))
+	entries := make([]*audit.Entry, 0, len(req.UserIDs))
+	for _, userID := range req.UserIDs {
+		if userID == 0 || seen[userID] {
+			continue
+		}
+		seen[userID] = true
+		entries = append(entries, &audit.Entry{
+			Action:     audit.ActionUserPasswordReset,
+			TargetType: audit.TargetUser,
+			TargetID:   strconv.FormatUint(uint64(userID), 10),
+			After:      map[string]interface{}{"must_reset_password": true, "job_id": job.ID},
+			Reason:     req.Reason,
+		})
+	}
+	recordAdminAudit(c, h.audit, h.logger, entries...)
+
+	utils.Success(c, job)
+}
+
+// GetBulkPasswordReset 查询批量强制重置密码任务进度
+//
+// @Summary 查询批量重置任务进度
+// @Description 返回任务状态和已标记、已发送邮件、失败的用户数，失败的用户ID可用于重新发起任务。任务进度保存在发起任务的实例中，结束24小时后清除
+// @Tags 系统
+// @Produce json
+// @Security BearerAuth
+// @Param job_id path string true "任务ID"
+// @Success 200 {object} utils.Response{data=user.PasswordResetJob} "任务进度"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "需要管理员权限"
+// @Failure 404 {object} utils.Response "任务不存在"
+// @Router /api/v1/admin/users/password-resets/{job_id} [get]
+func (h *AdminPasswordResetHandler) GetBulkPasswordReset(c *gin.Context) {
+	job, err := h.service.GetJob(c.Request.Context(), c.Param("job_id"))
+	if err != nil {
+		respondServiceError(c, err, "查询重置任务失败")
+		return
+	}
+	utils.Success(c, job)
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/service/audit"
+	"cloudpan/internal/service/user"
+)
+
+// MockPasswordResetService 模拟批量强制重置密码服务
+type MockPasswordResetService struct {
+	mock.Mock
+}
+
+func (m *MockPasswordResetService) StartBulkReset(ctx context.Context, adminID uint, req user.BulkPasswordResetRequest) (*user.PasswordResetJob, error) {
+	args := m.Called(ctx, adminID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*user.PasswordResetJob), args.Error(1)
+}
+
+func (m *MockPasswordResetService) GetJob(ctx context.Context, jobID string) (*user.PasswordResetJob, error) {
+	args := m.Called(ctx, jobID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*user.PasswordResetJob), args.Error(1)
+}
+
+func setupAdminPasswordResetRouter(service *MockPasswordResetService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler := NewAdminPasswordResetHandler(service, zap.NewNop())
+	admin := router.Group("", func(c *gin.Context) {
+		c.Set("user_id", uint64(1))
+	})
+	admin.POST("/admin/users/password-resets", handler.StartBulkPasswordReset)
+	admin.GET("/admin/users/password-resets/:job_id", handler.GetBulkPasswordReset)
+	return router
+}
+
+func TestAdminPasswordResetHandler_StartBulkPasswordReset(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		service := new(MockPasswordResetService)
+		service.On("StartBulkReset", mock.Anything, uint(1), user.BulkPasswordResetRequest{UserIDs: []uint{7, 8}, Reason: "凭据泄露"}).
+			Return(&user.PasswordResetJob{ID: "job1", Status: user.PasswordResetJobRunning, Total: 2}, nil)
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/admin/users/password-resets",
+			strings.NewReader(`{"user_ids":[7,8],"reason":"凭据泄露"}`))
+		req.Header.Set("Content-Type", "application/json")
+		setupAdminPasswordResetRouter(service).ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Data user.PasswordResetJob `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "job1", resp.Data.ID)
+		assert.Equal(t, 2, resp.Data.Total)
+	})
+
+	t.Run("records audit log per user", func(t *testing.T) {
+		service := new(MockPasswordResetService)
+		service.On("StartBulkReset", mock.Anything, uint(1), mock.Anything).
+			Return(&user.PasswordResetJob{ID: "job1", Status: user.PasswordResetJobRunning, Total: 2}, nil)
+		auditService := &recordingAuditService{}
+		handler := NewAdminPasswordResetHandler(service, zap.NewNop())
+		handler.SetAuditService(auditService)
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		router.POST("/admin/users/password-resets", func(c *gin.Context) { c.Set("user_id", uint64(1)) }, handler.StartBulkPasswordReset)
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/admin/users/password-resets",
+			strings.NewReader(`{"user_ids":[7,8,7],"reason":"凭据泄露"}`))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Len(t, auditService.entries, 2)
+		for i, targetID := range []string{"7", "8"} {
+			entry := auditService.entries[i]
+			assert.Equal(t, audit.ActionUserPasswordReset, entry.Action)
+			assert.Equal(t, audit.TargetUser, entry.TargetType)
+			assert.Equal(t, targetID, entry.TargetID)
+			assert.Equal(t, "凭据泄露", entry.Reason)
+			assert.Equal(t, "job1", entry.After["job_id"])
+		}
+	})
+
+	t.Run("missing user ids", func(t *testing.T) {
+		service := new(MockPasswordResetService)
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/admin/users/password-resets", strings.NewReader(`{"reason":"x"}`))
+		req.Header.Set("Content-Type", "application/json")
+		setupAdminPasswordResetRouter(service).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		service.AssertNotCalled(t, "StartBulkReset", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("too many users", func(t *testing.T) {
+		service := new(MockPasswordResetService)
+		service.On("StartBulkReset", mock.Anything, uint(1), mock.Anything).
+			Return(nil, pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "单次最多重置1个用户"))
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/admin/users/password-resets", strings.NewReader(`{"user_ids":[7,8]}`))
+		req.Header.Set("Content-Type", "application/json")
+		setupAdminPasswordResetRouter(service).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, utils.CodeValidationError, decodeShareResponse(t, w).Code)
+	})
+}
+
+func TestAdminPasswordResetHandler_GetBulkPasswordReset(t *testing.T) {
+	service := new(MockPasswordResetService)
+	service.On("GetJob", mock.Anything, "job1").
+		Return(&user.PasswordResetJob{ID: "job1", Status: user.PasswordResetJobCompleted, Flagged: 2, EmailsQueued: 2}, nil)
+	service.On("GetJob", mock.Anything, "missing").
+		Return(nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "重置任务不存在或已过期"))
+	router := setupAdminPasswordResetRouter(service)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/users/password-resets/job1", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Data user.PasswordResetJob `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, user.PasswordResetJobCompleted, resp.Data.Status)
+	assert.Equal(t, 2, resp.Data.EmailsQueued)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/users/password-resets/missing", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/service/audit"
+	filesvc "cloudpan/internal/service/file"
+)
+
+// 保留存储对象列表的分页大小
+const (
+	defaultRetainedContentPageSize = 20
+	maxRetainedContentPageSize     = 100
+)
+
+// RestoreRetainedContentRequest 从保留的存储对象恢复文件请求
+type RestoreRetainedContentRequest struct {
+	Reason string `json:"reason" binding:"max=500"` // 恢复原因，记录在审计日志中
+}
+
+// AdminRetainedContentHandler 管理员查看和恢复删除后保留的存储对象的处理器
+type AdminRetainedContentHandler struct {
+	service filesvc.ContentRetentionService
+	audit   audit.AdminAuditService
+	logger  *zap.Logger
+}
+
+// NewAdminRetainedContentHandler 创建保留存储对象管理处理器
+func NewAdminRetainedContentHandler(service filesvc.ContentRetentionService, logger *zap.Logger) *AdminRetainedContentHandler {
+	return &AdminRetainedContentHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// SetAuditService 设置管理员操作审计服务，未设置时不记录审计日志
+func (h *AdminRetainedContentHandler) SetAuditService(service audit.AdminAuditService) {
+	h.audit = service
+}
+
+// ListRetained 分页查询删除后保留的存储对象
+//
+// @Summary 查询保留的存储对象
+// @Description 回收站彻底删除后文件记录已删除、存储对象仍在保留期内的文件，含已恢复的记录；指定用户ID时只查询该用户
+// @Tags 系统
+// @Produce json
+// @Security BearerAuth
+// @Param user_id query int false "原文件所属用户ID"
+// @Param page query int false "页码，默认1"
+// @Param page_size query int false "每页记录数，默认20，最大100"
+// @Success 200 {object} utils.Response{data=[]models.RetainedObject} "查询成功"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "需要管理员权限"
+// @Failure 500 {object} utils.Response "内部服务器错误"
+// @Router /api/v1/admin/retained-content [get]
+func (h *AdminRetainedContentHandler) ListRetained(c *gin.Context) {
+	var userID uint64
+	if raw := c.Query("user_id"); raw != "" {
+		parsed, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			utils.ErrorWithMessage(c, utils.CodeBadRequest, "用户ID格式错误")
+			return
+		}
+		userID = parsed
+	}
+	page, _ := strconv.Atoi(c.Query("page"))
+	if page < 1 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(c.Query("page_size"))
+	if pageSize < 1 {
+		pageSize = defaultRetainedContentPageSize
+	}
+	pageSize = min(pageSize, maxRetainedContentPageSize)
+
+	objects, total, err := h.service.ListRetained(c.Request.Context(), uint(userID), page, pageSize)
+	if err != nil {
+		respondServiceError(c, err, "查询保留的存储对象失败")
+		return
+	}
+
+	utils.SuccessList(c, objects, utils.NewPagination(page, pageSize, total))
+}
+
+// RestoreRetained 从保留的存储对象恢复文件
+//
+// @Summary 从保留的存储对象恢复文件
+// @Description 用于误删等紧急情况：根据保留期内的存储对象重新创建文件记录，恢复到原用户的根目录，
+// @Description 同名冲突时自动重命名，并重新计入用户的存储用量。每条记录只能恢复一次
+// @Tags 系统
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "保留记录ID"
+// @Param request body RestoreRetainedContentRequest false "恢复原因"
+// @Success 200 {object} utils.Response{data=filesvc.RestoredFile} "恢复成功"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "需要管理员权限，或存储对象已恢复、已过保留期"
+// @Failure 404 {object} utils.Response "保留记录或原用户不存在"
+// @Failure 409 {object} utils.Response "根目录同名文件过多"
+// @Router /api/v1/admin/retained-content/{id}/restore [post]
+func (h *AdminRetainedContentHandler) RestoreRetained(c *gin.Context) {
+	adminID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "保留记录ID格式错误")
+		return
+	}
+
+	var req RestoreRetainedContentRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+			return
+		}
+	}
+
+	restored, err := h.service.RestoreRetained(c.Request.Context(), adminID, id)
+	if err != nil {
+		respondServiceError(c, err, "恢复文件失败")
+		return
+	}
+
+	h.logger.Warn("File restored from retained content by admin",
+		zap.Uint("admin_id", adminID),
+		zap.Uint("retained_id", id),
+		zap.Uint("file_id", restored.FileID),
+		zap.String("ip", c.ClientIP()))
+	recordAdminAudit(c, h.audit, h.logger, &audit.Entry{
+		Action:     audit.ActionFileContentRestore,
+		TargetType: audit.TargetFile,
+		TargetID:   strconv.FormatUint(uint64(restored.FileID), 10),
+		Before:     map[string]interface{}{"retained_id": id},
+		After:      map[string]interface{}{"file_id": restored.FileID, "path": restored.Path},
+		Reason:     req.Reason,
+	})
+
+	utils.Success(c, restored)
+}
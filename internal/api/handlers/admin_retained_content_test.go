@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+	"cloudpan/internal/service/audit"
+	filesvc "cloudpan/internal/service/file"
+)
+
+// stubContentRetentionService 记录调用参数的存储对象保留服务
+type stubContentRetentionService struct {
+	filesvc.ContentRetentionService
+	userID   uint
+	page     int
+	pageSize int
+	adminID  uint
+}
+
+func (s *stubContentRetentionService) ListRetained(_ context.Context, userID uint, page, pageSize int) ([]*models.RetainedObject, int64, error) {
+	s.userID, s.page, s.pageSize = userID, page, pageSize
+	return []*models.RetainedObject{{UserID: 7, FileID: 3, Name: "a.txt"}}, 1, nil
+}
+
+func (s *stubContentRetentionService) RestoreRetained(_ context.Context, adminID, id uint) (*filesvc.RestoredFile, error) {
+	s.adminID = adminID
+	switch id {
+	case 1:
+		return &filesvc.RestoredFile{FileID: 42, Name: "a (1).txt", Path: "/a (1).txt", Relocated: true, Renamed: true}, nil
+	case 2:
+		return nil, pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "存储对象已恢复或已过保留期")
+	default:
+		return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "保留记录不存在")
+	}
+}
+
+func setupAdminRetainedContentRouter(service filesvc.ContentRetentionService, auditService audit.AdminAuditService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler := NewAdminRetainedContentHandler(service, zap.NewNop())
+	handler.SetAuditService(auditService)
+	admin := router.Group("/admin/retained-content", func(c *gin.Context) {
+		c.Set("user_id", uint64(1))
+	})
+	admin.GET("", handler.ListRetained)
+	admin.POST("/:id/restore", handler.RestoreRetained)
+	return router
+}
+
+func TestAdminRetainedContentHandler_ListRetained(t *testing.T) {
+	service := &stubContentRetentionService{}
+	router := setupAdminRetainedContentRouter(service, nil)
+
+	w := serveAdminEmail(router, http.MethodGet, "/admin/retained-content?user_id=7&page=2&page_size=500", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, uint(7), service.userID)
+	assert.Equal(t, 2, service.page)
+	assert.Equal(t, maxRetainedContentPageSize, service.pageSize)
+	assert.Contains(t, w.Body.String(), `"file_id":3`)
+
+	w = serveAdminEmail(router, http.MethodGet, "/admin/retained-content?user_id=abc", "")
+	assert.Equal(t, utils.CodeBadRequest, decodeShareResponse(t, w).Code)
+}
+
+func TestAdminRetainedContentHandler_RestoreRetained(t *testing.T) {
+	service := &stubContentRetentionService{}
+	recorder := &recordingAuditService{}
+	router := setupAdminRetainedContentRouter(service, recorder)
+
+	w := serveAdminEmail(router, http.MethodPost, "/admin/retained-content/1/restore", `{"reason":"误删恢复"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, uint(1), service.adminID)
+	assert.Contains(t, w.Body.String(), `"file_id":42`)
+	require.Len(t, recorder.entries, 1)
+	entry := recorder.entries[0]
+	assert.Equal(t, audit.ActionFileContentRestore, entry.Action)
+	assert.Equal(t, audit.TargetFile, entry.TargetType)
+	assert.Equal(t, "42", entry.TargetID)
+	assert.Equal(t, "误删恢复", entry.Reason)
+
+	// 已恢复或不存在的记录不写审计日志
+	w = serveAdminEmail(router, http.MethodPost, "/admin/retained-content/2/restore", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = serveAdminEmail(router, http.MethodPost, "/admin/retained-content/3/restore", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Len(t, recorder.entries, 1)
+
+	w = serveAdminEmail(router, http.MethodPost, "/admin/retained-content/x/restore", "")
+	assert.Equal(t, utils.CodeBadRequest, decodeShareResponse(t, w).Code)
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/service/audit"
+	sharesvc "cloudpan/internal/service/share"
+)
+
+// 举报审核队列的分页大小
+const (
+	defaultShareReportPageSize = 20
+	maxShareReportPageSize     = 100
+)
+
+// ResolveShareReportRequest 审核分享举报请求
+type ResolveShareReportRequest struct {
+	Action string `json:"action" binding:"required"` // dismiss 驳回并恢复被暂停的分享；disable 确认违规并停用分享
+	Note   string `json:"note"`                      // 审核备注，最多500个字符，记录在举报和审计日志中
+}
+
+// AdminShareAbuseHandler 管理员分享举报审核处理器
+type AdminShareAbuseHandler struct {
+	service sharesvc.AbuseService
+	audit   audit.AdminAuditService
+	logger  *zap.Logger
+}
+
+// NewAdminShareAbuseHandler 创建分享举报审核处理器
+func NewAdminShareAbuseHandler(service sharesvc.AbuseService, logger *zap.Logger) *AdminShareAbuseHandler {
+	return &AdminShareAbuseHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// SetAuditService 设置管理员操作审计服务，未设置时不记录审计日志
+func (h *AdminShareAbuseHandler) SetAuditService(service audit.AdminAuditService) {
+	h.audit = service
+}
+
+// ListReports 分页查询分享举报
+//
+// @Summary 查询分享举报审核队列
+// @Description 按举报时间倒序返回举报，默认只返回待审核的举报；status=all 返回全部状态，可按分享ID筛选
+// @Tags 系统
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "审核状态：pending(默认)/dismissed/actioned/all"
+// @Param share_id query int false "分享ID"
+// @Param page query int false "页码，默认1"
+// @Param page_size query int false "每页记录数，默认20，最大100"
+// @Success 200 {object} utils.Response{data=[]models.ShareAbuseReport} "查询成功"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "需要管理员权限"
+// @Failure 500 {object} utils.Response "内部服务器错误"
+// @Router /api/v1/admin/share-reports [get]
+func (h *AdminShareAbuseHandler) ListReports(c *gin.Context) {
+	status := c.DefaultQuery("status", "pending")
+	if status == "all" {
+		status = ""
+	}
+	var shareID uint64
+	if raw := c.Query("share_id"); raw != "" {
+		parsed, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			utils.ErrorWithMessage(c, utils.CodeBadRequest, "分享ID格式错误")
+			return
+		}
+		shareID = parsed
+	}
+	page, _ := strconv.Atoi(c.Query("page"))
+	if page < 1 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(c.Query("page_size"))
+	if pageSize < 1 {
+		pageSize = defaultShareReportPageSize
+	}
+	pageSize = min(pageSize, maxShareReportPageSize)
+
+	reports, total, err := h.service.ListReports(c.Request.Context(), status, uint(shareID), page, pageSize)
+	if err != nil {
+		respondServiceError(c, err, "查询分享举报失败")
+		return
+	}
+
+	utils.SuccessList(c, reports, utils.NewPagination(page, pageSize, total))
+}
+
+// ResolveReport 审核分享举报
+//
+// @Summary 审核分享举报
+// @Description 同一分享的全部待审核举报一起处理：dismiss 驳回举报，被自动暂停的分享恢复有效；
+// @Description disable 确认违规，停用分享。处理结果通过站内通知告知分享者
+// @Tags 系统
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "举报ID"
+// @Param request body ResolveShareReportRequest true "审核操作"
+// @Success 200 {object} utils.Response{data=share.AbuseResolution} "审核完成"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "需要管理员权限，或举报已处理"
+// @Failure 404 {object} utils.Response "举报不存在"
+// @Router /api/v1/admin/share-reports/{id}/resolve [post]
+func (h *AdminShareAbuseHandler) ResolveReport(c *gin.Context) {
+	adminID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	reportID, ok := parseIDParam(c, "id")
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "举报ID格式错误")
+		return
+	}
+
+	var req ResolveShareReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+		return
+	}
+
+	resolution, err := h.service.Resolve(c.Request.Context(), adminID, reportID, req.Action, req.Note)
+	if err != nil {
+		respondServiceError(c, err, "审核分享举报失败")
+		return
+	}
+
+	h.logger.Warn("Share abuse reports resolved by admin",
+		zap.Uint("admin_id", adminID),
+		zap.Uint("report_id", reportID),
+		zap.Uint("share_id", resolution.ShareID),
+		zap.String("action", req.Action),
+		zap.String("ip", c.ClientIP()))
+	recordAdminAudit(c, h.audit, h.logger, &audit.Entry{
+		Action:     audit.ActionShareAbuseResolve,
+		TargetType: audit.TargetShare,
+		TargetID:   strconv.FormatUint(uint64(resolution.ShareID), 10),
+		Before:     map[string]interface{}{"report_id": reportID},
+		After:      map[string]interface{}{"action": req.Action, "share_status": resolution.ShareStatus, "resolved": resolution.Resolved},
+		Reason:     req.Note,
+	})
+
+	utils.Success(c, resolution)
+}
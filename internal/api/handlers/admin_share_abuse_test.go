@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/service/audit"
+	sharesvc "cloudpan/internal/service/share"
+)
+
+func setupAdminShareAbuseRouter(service sharesvc.AbuseService, auditService audit.AdminAuditService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler := NewAdminShareAbuseHandler(service, zap.NewNop())
+	handler.SetAuditService(auditService)
+	admin := router.Group("/admin/share-reports", func(c *gin.Context) {
+		c.Set("user_id", uint64(1))
+	})
+	admin.GET("", handler.ListReports)
+	admin.POST("/:id/resolve", handler.ResolveReport)
+	return router
+}
+
+func TestAdminShareAbuseHandler_ListReports(t *testing.T) {
+	service := &stubAbuseService{}
+	router := setupAdminShareAbuseRouter(service, nil)
+
+	w := serveAdminEmail(router, http.MethodGet, "/admin/share-reports?page_size=500", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "pending", service.status, "默认只查询待审核举报")
+	assert.Equal(t, maxShareReportPageSize, service.pageSize)
+	assert.Contains(t, w.Body.String(), `"reason":"spam"`)
+
+	w = serveAdminEmail(router, http.MethodGet, "/admin/share-reports?status=all&share_id=3", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "", service.status)
+	assert.Equal(t, uint(3), service.shareID)
+
+	w = serveAdminEmail(router, http.MethodGet, "/admin/share-reports?share_id=x", "")
+	assert.Equal(t, utils.CodeBadRequest, decodeShareResponse(t, w).Code)
+}
+
+func TestAdminShareAbuseHandler_ResolveReport(t *testing.T) {
+	service := &stubAbuseService{}
+	recorder := &recordingAuditService{}
+	router := setupAdminShareAbuseRouter(service, recorder)
+
+	w := serveAdminEmail(router, http.MethodPost, "/admin/share-reports/1/resolve", `{"action":"disable","note":"钓鱼页面"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, uint(1), service.adminID)
+	assert.Equal(t, "disable", service.action)
+	assert.Contains(t, w.Body.String(), `"share_status":"disabled"`)
+	require.Len(t, recorder.entries, 1)
+	entry := recorder.entries[0]
+	assert.Equal(t, audit.ActionShareAbuseResolve, entry.Action)
+	assert.Equal(t, audit.TargetShare, entry.TargetType)
+	assert.Equal(t, "3", entry.TargetID)
+	assert.Equal(t, "钓鱼页面", entry.Reason)
+
+	// 不存在的举报不写审计日志
+	w = serveAdminEmail(router, http.MethodPost, "/admin/share-reports/2/resolve", `{"action":"dismiss"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Len(t, recorder.entries, 1)
+
+	w = serveAdminEmail(router, http.MethodPost, "/admin/share-reports/1/resolve", `{}`)
+	assert.Equal(t, utils.CodeBadRequest, decodeShareResponse(t, w).Code)
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+	"cloudpan/internal/service/audit"
+	sharesvc "cloudpan/internal/service/share"
+)
+
+// 内容审核复核队列的分页大小
+const (
+	defaultModerationPageSize = 20
+	maxModerationPageSize     = 100
+)
+
+// OverrideModerationRequest 复核内容审核结果请求
+type OverrideModerationRequest struct {
+	Decision string `json:"decision" binding:"required"` // approve 放行，允许公开分享；reject 禁止，停用文件的有效分享
+	Note     string `json:"note"`                        // 复核备注，最多500个字符，记录在审核结果和审计日志中
+}
+
+// AdminShareModerationHandler 管理员分享图片内容审核复核处理器
+type AdminShareModerationHandler struct {
+	service sharesvc.ModerationService
+	audit   audit.AdminAuditService
+	logger  *zap.Logger
+}
+
+// NewAdminShareModerationHandler 创建内容审核复核处理器
+func NewAdminShareModerationHandler(service sharesvc.ModerationService, logger *zap.Logger) *AdminShareModerationHandler {
+	return &AdminShareModerationHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// SetAuditService 设置管理员操作审计服务，未设置时不记录审计日志
+func (h *AdminShareModerationHandler) SetAuditService(service audit.AdminAuditService) {
+	h.audit = service
+}
+
+// ListResults 分页查询内容审核结果
+//
+// @Summary 查询内容审核复核队列
+// @Description 按审核时间倒序返回分享图片的内容审核结果，默认只返回未复核的疑似违规(flagged)和违规(blocked)结果；
+// @Description status 可以为逗号分隔的多个结论，status=all 返回全部结论，reviewed=true 同时返回已复核的结果
+// @Tags 系统
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "审核结论：clean/flagged/blocked/failed/all，默认flagged,blocked"
+// @Param reviewed query bool false "是否包含已复核的结果，默认false"
+// @Param page query int false "页码，默认1"
+// @Param page_size query int false "每页记录数，默认20，最大100"
+// @Success 200 {object} utils.Response{data=[]models.ContentModerationResult} "查询成功"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "需要管理员权限"
+// @Failure 500 {object} utils.Response "内部服务器错误"
+// @Router /api/v1/admin/share-moderation [get]
+func (h *AdminShareModerationHandler) ListResults(c *gin.Context) {
+	statuses := []string{models.ModerationStatusFlagged, models.ModerationStatusBlocked}
+	if raw := c.Query("status"); raw == "all" {
+		statuses = nil
+	} else if raw != "" {
+		statuses = strings.Split(raw, ",")
+	}
+	includeReviewed, _ := strconv.ParseBool(c.Query("reviewed"))
+	page, _ := strconv.Atoi(c.Query("page"))
+	if page < 1 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(c.Query("page_size"))
+	if pageSize < 1 {
+		pageSize = defaultModerationPageSize
+	}
+	pageSize = min(pageSize, maxModerationPageSize)
+
+	results, total, err := h.service.ListResults(c.Request.Context(), statuses, !includeReviewed, page, pageSize)
+	if err != nil {
+		respondServiceError(c, err, "查询内容审核结果失败")
+		return
+	}
+
+	utils.SuccessList(c, results, utils.NewPagination(page, pageSize, total))
+}
+
+// OverrideResult 复核内容审核结果
+//
+// @Summary 复核内容审核结果
+// @Description approve 放行误判的图片，之后可以正常公开分享(已停用的分享不恢复)；reject 确认违规，
+// @Description 停用该文件的全部有效分享并通知文件所有者，之后不能再公开分享。可以修改已有的复核结论，文件内容变化后重新审核
+// @Tags 系统
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "审核结果ID"
+// @Param request body OverrideModerationRequest true "复核结论"
+// @Success 200 {object} utils.Response{data=share.ModerationReview} "复核完成"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "需要管理员权限"
+// @Failure 404 {object} utils.Response "审核结果不存在"
+// @Router /api/v1/admin/share-moderation/{id}/override [post]
+func (h *AdminShareModerationHandler) OverrideResult(c *gin.Context) {
+	adminID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	resultID, ok := parseIDParam(c, "id")
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "审核结果ID格式错误")
+		return
+	}
+
+	var req OverrideModerationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+		return
+	}
+
+	review, err := h.service.Override(c.Request.Context(), adminID, resultID, req.Decision, req.Note)
+	if err != nil {
+		respondServiceError(c, err, "复核内容审核结果失败")
+		return
+	}
+
+	h.logger.Warn("Content moderation result overridden by admin",
+		zap.Uint("admin_id", adminID),
+		zap.Uint("result_id", resultID),
+		zap.Uint("file_id", review.FileID),
+		zap.String("override", review.Override),
+		zap.String("ip", c.ClientIP()))
+	recordAdminAudit(c, h.audit, h.logger, &audit.Entry{
+		Action:     audit.ActionModerationOverride,
+		TargetType: audit.TargetFile,
+		TargetID:   strconv.FormatUint(uint64(review.FileID), 10),
+		Before:     map[string]interface{}{"result_id": resultID},
+		After:      map[string]interface{}{"override": review.Override, "disabled_shares": review.DisabledShares},
+		Reason:     req.Note,
+	})
+
+	utils.Success(c, review)
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+	"cloudpan/internal/service/audit"
+	sharesvc "cloudpan/internal/service/share"
+)
+
+// stubModerationService 记录调用参数的内容审核服务
+type stubModerationService struct {
+	checked        []uint
+	statuses       []string
+	unreviewedOnly bool
+	pageSize       int
+	decision       string
+}
+
+func (s *stubModerationService) Check(_ context.Context, fileID uint) error {
+	s.checked = append(s.checked, fileID)
+	if fileID == 13 {
+		return pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "图片内容违反分享规范，不能公开分享")
+	}
+	return nil
+}
+
+func (s *stubModerationService) ListResults(_ context.Context, statuses []string, unreviewedOnly bool, page, pageSize int) ([]*models.ContentModerationResult, int64, error) {
+	s.statuses, s.unreviewedOnly, s.pageSize = statuses, unreviewedOnly, pageSize
+	return []*models.ContentModerationResult{{FileID: 5, Status: models.ModerationStatusFlagged, Label: "nsfw"}}, 1, nil
+}
+
+func (s *stubModerationService) Override(_ context.Context, adminID, resultID uint, decision, note string) (*sharesvc.ModerationReview, error) {
+	s.decision = decision
+	if resultID != 1 {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "审核结果不存在")
+	}
+	return &sharesvc.ModerationReview{FileID: 5, Override: models.ModerationOverrideRejected, DisabledShares: 2}, nil
+}
+
+func setupAdminShareModerationRouter(service sharesvc.ModerationService, auditService audit.AdminAuditService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler := NewAdminShareModerationHandler(service, zap.NewNop())
+	handler.SetAuditService(auditService)
+	admin := router.Group("/admin/share-moderation", func(c *gin.Context) {
+		c.Set("user_id", uint64(1))
+	})
+	admin.GET("", handler.ListResults)
+	admin.POST("/:id/override", handler.OverrideResult)
+	return router
+}
+
+func TestAdminShareModerationHandler_ListResults(t *testing.T) {
+	service := &stubModerationService{}
+	router := setupAdminShareModerationRouter(service, nil)
+
+	w := serveAdminEmail(router, http.MethodGet, "/admin/share-moderation?page_size=500", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{models.ModerationStatusFlagged, models.ModerationStatusBlocked}, service.statuses, "默认只查询疑似违规和违规结果")
+	assert.True(t, service.unreviewedOnly)
+	assert.Equal(t, maxModerationPageSize, service.pageSize)
+	assert.Contains(t, w.Body.String(), `"label":"nsfw"`)
+
+	w = serveAdminEmail(router, http.MethodGet, "/admin/share-moderation?status=all&reviewed=true", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Nil(t, service.statuses)
+	assert.False(t, service.unreviewedOnly)
+
+	serveAdminEmail(router, http.MethodGet, "/admin/share-moderation?status=failed,clean", "")
+	assert.Equal(t, []string{models.ModerationStatusFailed, models.ModerationStatusClean}, service.statuses)
+}
+
+func TestAdminShareModerationHandler_OverrideResult(t *testing.T) {
+	service := &stubModerationService{}
+	recorder := &recordingAuditService{}
+	router := setupAdminShareModerationRouter(service, recorder)
+
+	w := serveAdminEmail(router, http.MethodPost, "/admin/share-moderation/1/override", `{"decision":"reject","note":"色情图片"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "reject", service.decision)
+	assert.Contains(t, w.Body.String(), `"disabled_shares":2`)
+	require.Len(t, recorder.entries, 1)
+	entry := recorder.entries[0]
+	assert.Equal(t, audit.ActionModerationOverride, entry.Action)
+	assert.Equal(t, audit.TargetFile, entry.TargetType)
+	assert.Equal(t, "5", entry.TargetID)
+	assert.Equal(t, "色情图片", entry.Reason)
+
+	w = serveAdminEmail(router, http.MethodPost, "/admin/share-moderation/2/override", `{"decision":"approve"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Len(t, recorder.entries, 1)
+
+	w = serveAdminEmail(router, http.MethodPost, "/admin/share-moderation/1/override", `{}`)
+	assert.Equal(t, utils.CodeBadRequest, decodeShareResponse(t, w).Code)
+}
@@ -0,0 +1,502 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+	"cloudpan/internal/service/audit"
+	"cloudpan/internal/service/sso"
+)
+
+// AdminSSOHandler 管理员配置企业单点登录的处理器
+type AdminSSOHandler struct {
+	service sso.Service
+	scim    sso.SCIMService
+	audit   audit.AdminAuditService
+	logger  *zap.Logger
+}
+
+// NewAdminSSOHandler 创建企业单点登录管理处理器
+func NewAdminSSOHandler(service sso.Service, logger *zap.Logger) *AdminSSOHandler {
+	return &AdminSSOHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// SetAuditService 设置管理员操作审计服务，未设置时不记录审计日志
+func (h *AdminSSOHandler) SetAuditService(service audit.AdminAuditService) {
+	h.audit = service
+}
+
+// SetSCIMService 设置目录同步服务，用于签发和吊销目录同步令牌
+func (h *AdminSSOHandler) SetSCIMService(service sso.SCIMService) {
+	h.scim = service
+}
+
+// SSODomainRequest 认领邮箱域名请求
+type SSODomainRequest struct {
+	Domain string `json:"domain" binding:"required" example:"acme.com"` // 邮箱域名
+}
+
+// ListProviders 列出身份提供方
+//
+// @Summary 列出单点登录身份提供方
+// @Description 返回全部租户的身份提供方及其认领的邮箱域名，客户端密钥不返回
+// @Tags 系统
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response{data=[]models.SSOProvider} "获取成功"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "需要管理员权限"
+// @Router /api/v1/admin/sso/providers [get]
+func (h *AdminSSOHandler) ListProviders(c *gin.Context) {
+	providers, err := h.service.ListProviders(c.Request.Context())
+	if err != nil {
+		respondServiceError(c, err, "获取身份提供方失败")
+		return
+	}
+	utils.Success(c, providers)
+}
+
+// GetProvider 获取身份提供方
+//
+// @Summary 获取单点登录身份提供方
+// @Tags 系统
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "身份提供方ID"
+// @Success 200 {object} utils.Response{data=models.SSOProvider} "获取成功"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "需要管理员权限"
+// @Failure 404 {object} utils.Response "身份提供方不存在"
+// @Router /api/v1/admin/sso/providers/{id} [get]
+func (h *AdminSSOHandler) GetProvider(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "身份提供方ID格式错误")
+		return
+	}
+	provider, err := h.service.GetProvider(c.Request.Context(), id)
+	if err != nil {
+		respondServiceError(c, err, "获取身份提供方失败")
+		return
+	}
+	utils.Success(c, provider)
+}
+
+// CreateProvider 创建身份提供方
+//
+// @Summary 创建单点登录身份提供方
+// @Description 为租户配置OIDC身份提供方，创建时访问签发者的发现文档校验配置。组到角色的映射只允许user、moderator、admin，默认开启即时开通账户、关闭强制SSO
+// @Tags 系统
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body sso.ProviderRequest true "身份提供方配置"
+// @Success 200 {object} utils.Response{data=models.SSOProvider} "创建成功"
+// @Failure 400 {object} utils.Response "配置不合法或签发者无法访问"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "需要管理员权限"
+// @Failure 409 {object} utils.Response "租户已配置身份提供方"
+// @Router /api/v1/admin/sso/providers [post]
+func (h *AdminSSOHandler) CreateProvider(c *gin.Context) {
+	var req sso.ProviderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+		return
+	}
+
+	provider, err := h.service.CreateProvider(c.Request.Context(), &req)
+	if err != nil {
+		respondServiceError(c, err, "创建身份提供方失败")
+		return
+	}
+
+	h.logger.Info("SSO provider created",
+		zap.Uint("provider_id", provider.ID),
+		zap.String("tenant", provider.Tenant),
+		zap.String("issuer", provider.Issuer),
+		zap.String("ip", c.ClientIP()))
+	after := ssoProviderSnapshot(provider)
+	after["client_secret_set"] = provider.ClientSecret != ""
+	recordAdminAudit(c, h.audit, h.logger, &audit.Entry{
+		Action:     audit.ActionSSOProviderChange,
+		TargetType: audit.TargetSSOProvider,
+		TargetID:   strconv.FormatUint(uint64(provider.ID), 10),
+		After:      after,
+	})
+
+	utils.Success(c, provider)
+}
+
+// UpdateProvider 更新身份提供方
+//
+// @Summary 更新单点登录身份提供方
+// @Description 更新身份提供方配置，租户标识不可修改，省略client_secret表示不修改密钥。修改签发者地址时重新校验发现文档
+// @Tags 系统
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "身份提供方ID"
+// @Param request body sso.ProviderRequest true "身份提供方配置"
+// @Success 200 {object} utils.Response{data=models.SSOProvider} "更新成功"
+// @Failure 400 {object} utils.Response "配置不合法或签发者无法访问"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "需要管理员权限或修改了租户标识"
+// @Failure 404 {object} utils.Response "身份提供方不存在"
+// @Router /api/v1/admin/sso/providers/{id} [put]
+func (h *AdminSSOHandler) UpdateProvider(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "身份提供方ID格式错误")
+		return
+	}
+	var req sso.ProviderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+		return
+	}
+
+	ctx := c.Request.Context()
+	before, err := h.service.GetProvider(ctx, id)
+	if err != nil {
+		respondServiceError(c, err, "更新身份提供方失败")
+		return
+	}
+	provider, err := h.service.UpdateProvider(ctx, id, &req)
+	if err != nil {
+		respondServiceError(c, err, "更新身份提供方失败")
+		return
+	}
+
+	h.logger.Info("SSO provider updated",
+		zap.Uint("provider_id", provider.ID),
+		zap.String("tenant", provider.Tenant),
+		zap.String("ip", c.ClientIP()))
+	after := ssoProviderSnapshot(provider)
+	after["client_secret_changed"] = req.ClientSecret != nil
+	recordAdminAudit(c, h.audit, h.logger, &audit.Entry{
+		Action:     audit.ActionSSOProviderChange,
+		TargetType: audit.TargetSSOProvider,
+		TargetID:   strconv.FormatUint(uint64(provider.ID), 10),
+		Before:     ssoProviderSnapshot(before),
+		After:      after,
+	})
+
+	utils.Success(c, provider)
+}
+
+// DeleteProvider 删除身份提供方
+//
+// @Summary 删除单点登录身份提供方
+// @Description 删除身份提供方及其认领的邮箱域名，已关联的账户保留，之后只能使用密码登录
+// @Tags 系统
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "身份提供方ID"
+// @Success 200 {object} utils.Response "删除成功"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "需要管理员权限"
+// @Failure 404 {object} utils.Response "身份提供方不存在"
+// @Router /api/v1/admin/sso/providers/{id} [delete]
+func (h *AdminSSOHandler) DeleteProvider(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "身份提供方ID格式错误")
+		return
+	}
+
+	ctx := c.Request.Context()
+	before, err := h.service.GetProvider(ctx, id)
+	if err != nil {
+		respondServiceError(c, err, "删除身份提供方失败")
+		return
+	}
+	if err := h.service.DeleteProvider(ctx, id); err != nil {
+		respondServiceError(c, err, "删除身份提供方失败")
+		return
+	}
+
+	h.logger.Warn("SSO provider deleted",
+		zap.Uint("provider_id", id),
+		zap.String("tenant", before.Tenant),
+		zap.String("ip", c.ClientIP()))
+	recordAdminAudit(c, h.audit, h.logger, &audit.Entry{
+		Action:     audit.ActionSSOProviderChange,
+		TargetType: audit.TargetSSOProvider,
+		TargetID:   strconv.FormatUint(uint64(id), 10),
+		Before:     ssoProviderSnapshot(before),
+	})
+
+	utils.SuccessWithMessage(c, "身份提供方已删除", nil)
+}
+
+// AddDomain 认领邮箱域名
+//
+// @Summary 认领邮箱域名
+// @Description 为身份提供方登记邮箱域名，返回需要在域名DNS中添加的TXT记录。验证通过前该域名的用户不会跳转到单点登录
+// @Tags 系统
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "身份提供方ID"
+// @Param request body SSODomainRequest true "邮箱域名"
+// @Success 200 {object} utils.Response{data=sso.DomainInfo} "登记成功"
+// @Failure 400 {object} utils.Response "域名格式错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "需要管理员权限"
+// @Failure 404 {object} utils.Response "身份提供方不存在"
+// @Failure 409 {object} utils.Response "域名已被认领"
+// @Router /api/v1/admin/sso/providers/{id}/domains [post]
+func (h *AdminSSOHandler) AddDomain(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "身份提供方ID格式错误")
+		return
+	}
+	var req SSODomainRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+		return
+	}
+
+	domain, err := h.service.AddDomain(c.Request.Context(), id, req.Domain)
+	if err != nil {
+		respondServiceError(c, err, "认领域名失败")
+		return
+	}
+
+	recordAdminAudit(c, h.audit, h.logger, &audit.Entry{
+		Action:     audit.ActionSSOProviderChange,
+		TargetType: audit.TargetSSOProvider,
+		TargetID:   strconv.FormatUint(uint64(id), 10),
+		After:      map[string]interface{}{"domain_added": domain.Domain},
+	})
+
+	utils.Success(c, domain)
+}
+
+// VerifyDomain 验证邮箱域名
+//
+// @Summary 验证邮箱域名
+// @Description 查询域名的DNS TXT记录，记录值与认领时返回的一致时标记为已验证，之后该域名的用户登录时跳转到身份提供方
+// @Tags 系统
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "身份提供方ID"
+// @Param domain_id path int true "域名ID"
+// @Success 200 {object} utils.Response{data=sso.DomainInfo} "验证成功"
+// @Failure 400 {object} utils.Response "未找到匹配的TXT记录"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "需要管理员权限"
+// @Failure 404 {object} utils.Response "域名不存在"
+// @Router /api/v1/admin/sso/providers/{id}/domains/{domain_id}/verify [post]
+func (h *AdminSSOHandler) VerifyDomain(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "身份提供方ID格式错误")
+		return
+	}
+	domainID, ok := parseIDParam(c, "domain_id")
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "域名ID格式错误")
+		return
+	}
+
+	domain, err := h.service.VerifyDomain(c.Request.Context(), id, domainID)
+	if err != nil {
+		respondServiceError(c, err, "验证域名失败")
+		return
+	}
+
+	h.logger.Info("SSO domain verified",
+		zap.Uint("provider_id", id),
+		zap.String("domain", domain.Domain),
+		zap.String("ip", c.ClientIP()))
+	recordAdminAudit(c, h.audit, h.logger, &audit.Entry{
+		Action:     audit.ActionSSOProviderChange,
+		TargetType: audit.TargetSSOProvider,
+		TargetID:   strconv.FormatUint(uint64(id), 10),
+		After:      map[string]interface{}{"domain_verified": domain.Domain},
+	})
+
+	utils.Success(c, domain)
+}
+
+// DeleteDomain 取消认领邮箱域名
+//
+// @Summary 取消认领邮箱域名
+// @Description 删除域名后该域名的用户不再跳转到单点登录，也不再受强制SSO限制
+// @Tags 系统
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "身份提供方ID"
+// @Param domain_id path int true "域名ID"
+// @Success 200 {object} utils.Response "删除成功"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "需要管理员权限"
+// @Failure 404 {object} utils.Response "域名不存在"
+// @Router /api/v1/admin/sso/providers/{id}/domains/{domain_id} [delete]
+func (h *AdminSSOHandler) DeleteDomain(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "身份提供方ID格式错误")
+		return
+	}
+	domainID, ok := parseIDParam(c, "domain_id")
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "域名ID格式错误")
+		return
+	}
+
+	if err := h.service.DeleteDomain(c.Request.Context(), id, domainID); err != nil {
+		respondServiceError(c, err, "删除域名失败")
+		return
+	}
+
+	recordAdminAudit(c, h.audit, h.logger, &audit.Entry{
+		Action:     audit.ActionSSOProviderChange,
+		TargetType: audit.TargetSSOProvider,
+		TargetID:   strconv.FormatUint(uint64(id), 10),
+		After:      map[string]interface{}{"domain_removed": domainID},
+	})
+
+	utils.SuccessWithMessage(c, "域名已删除", nil)
+}
+
+// GetSCIMToken 查询目录同步令牌
+//
+// @Summary 查询目录同步令牌
+// @Description 返回身份提供方目录同步(SCIM)令牌的前缀、签发人和最近使用时间，不返回明文令牌
+// @Tags 系统
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "身份提供方ID"
+// @Success 200 {object} utils.Response{data=sso.SCIMTokenInfo} "获取成功"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "需要管理员权限"
+// @Failure 404 {object} utils.Response "身份提供方不存在或尚未签发令牌"
+// @Router /api/v1/admin/sso/providers/{id}/scim-token [get]
+func (h *AdminSSOHandler) GetSCIMToken(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "身份提供方ID格式错误")
+		return
+	}
+	info, err := h.scim.GetToken(c.Request.Context(), id)
+	if err != nil {
+		respondServiceError(c, err, "获取目录同步令牌失败")
+		return
+	}
+	utils.Success(c, info)
+}
+
+// IssueSCIMToken 签发目录同步令牌
+//
+// @Summary 签发目录同步令牌
+// @Description 为身份提供方签发目录同步(SCIM)令牌，填写到Okta、Azure AD等目录的SCIM配置中。明文令牌只在本次响应中返回，已有令牌立即失效；目录同步创建的团队归签发令牌的管理员所有
+// @Tags 系统
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "身份提供方ID"
+// @Success 200 {object} utils.Response{data=sso.SCIMTokenInfo} "签发成功"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "需要管理员权限"
+// @Failure 404 {object} utils.Response "身份提供方不存在"
+// @Router /api/v1/admin/sso/providers/{id}/scim-token [post]
+func (h *AdminSSOHandler) IssueSCIMToken(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "身份提供方ID格式错误")
+		return
+	}
+	adminID, _ := getCurrentUserID(c)
+
+	info, err := h.scim.IssueToken(c.Request.Context(), id, adminID)
+	if err != nil {
+		respondServiceError(c, err, "签发目录同步令牌失败")
+		return
+	}
+
+	h.logger.Warn("SCIM token issued",
+		zap.Uint("provider_id", id),
+		zap.Uint("admin_id", adminID),
+		zap.String("ip", c.ClientIP()))
+	recordAdminAudit(c, h.audit, h.logger, &audit.Entry{
+		Action:     audit.ActionSCIMTokenChange,
+		TargetType: audit.TargetSSOProvider,
+		TargetID:   strconv.FormatUint(uint64(id), 10),
+		After:      map[string]interface{}{"token_prefix": info.TokenPrefix},
+	})
+
+	utils.SuccessWithMessage(c, "目录同步令牌已签发，请立即保存", info)
+}
+
+// RevokeSCIMToken 吊销目录同步令牌
+//
+// @Summary 吊销目录同步令牌
+// @Description 吊销后目录无法再调用SCIM接口，已同步的用户和团队保留
+// @Tags 系统
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "身份提供方ID"
+// @Success 200 {object} utils.Response "吊销成功"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "需要管理员权限"
+// @Failure 404 {object} utils.Response "身份提供方不存在或尚未签发令牌"
+// @Router /api/v1/admin/sso/providers/{id}/scim-token [delete]
+func (h *AdminSSOHandler) RevokeSCIMToken(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "身份提供方ID格式错误")
+		return
+	}
+
+	ctx := c.Request.Context()
+	before, err := h.scim.GetToken(ctx, id)
+	if err != nil {
+		respondServiceError(c, err, "吊销目录同步令牌失败")
+		return
+	}
+	if err := h.scim.RevokeToken(ctx, id); err != nil {
+		respondServiceError(c, err, "吊销目录同步令牌失败")
+		return
+	}
+
+	h.logger.Warn("SCIM token revoked",
+		zap.Uint("provider_id", id),
+		zap.String("ip", c.ClientIP()))
+	recordAdminAudit(c, h.audit, h.logger, &audit.Entry{
+		Action:     audit.ActionSCIMTokenChange,
+		TargetType: audit.TargetSSOProvider,
+		TargetID:   strconv.FormatUint(uint64(id), 10),
+		Before:     map[string]interface{}{"token_prefix": before.TokenPrefix},
+	})
+
+	utils.SuccessWithMessage(c, "目录同步令牌已吊销", nil)
+}
+
+// ssoProviderSnapshot 审计记录中的身份提供方配置，不包含客户端密钥
+func ssoProviderSnapshot(provider *models.SSOProvider) map[string]interface{} {
+	snapshot := map[string]interface{}{
+		"tenant":       provider.Tenant,
+		"name":         provider.Name,
+		"issuer":       provider.Issuer,
+		"client_id":    provider.ClientID,
+		"scopes":       provider.Scopes,
+		"groups_claim": provider.GroupsClaim,
+		"default_role": provider.DefaultRole,
+		"jit_enabled":  provider.JITEnabled,
+		"enforce_sso":  provider.EnforceSSO,
+		"is_active":    provider.IsActive,
+	}
+	if provider.RoleMappings != nil {
+		snapshot["role_mappings"] = map[string]interface{}(*provider.RoleMappings)
+	}
+	return snapshot
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/repository/models"
+	"cloudpan/internal/service/audit"
+)
+
+func setupAdminSSORouter(service *stubSSOService) (*gin.Engine, *recordingAuditService) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	auditService := &recordingAuditService{}
+	handler := NewAdminSSOHandler(service, zap.NewNop())
+	handler.SetAuditService(auditService)
+	admin := router.Group("", func(c *gin.Context) {
+		c.Set("user_id", uint64(1))
+	})
+	admin.POST("/admin/sso/providers", handler.CreateProvider)
+	admin.PUT("/admin/sso/providers/:id", handler.UpdateProvider)
+	return router, auditService
+}
+
+func TestAdminSSOHandler_CreateProvider(t *testing.T) {
+	body := `{"tenant":"acme","name":"Acme","issuer":"https://login.acme.com","client_id":"cloudpan","client_secret":"s3cret"}`
+
+	t.Run("records audit log without secret", func(t *testing.T) {
+		router, auditService := setupAdminSSORouter(&stubSSOService{})
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/admin/sso/providers", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), "s3cret")
+		require.Len(t, auditService.entries, 1)
+		entry := auditService.entries[0]
+		assert.Equal(t, audit.ActionSSOProviderChange, entry.Action)
+		assert.Equal(t, audit.TargetSSOProvider, entry.TargetType)
+		assert.Equal(t, "5", entry.TargetID)
+		assert.Equal(t, "acme", entry.After["tenant"])
+		assert.Equal(t, true, entry.After["client_secret_set"])
+		for _, value := range entry.After {
+			assert.NotEqual(t, "s3cret", value)
+		}
+	})
+
+	t.Run("conflict", func(t *testing.T) {
+		router, auditService := setupAdminSSORouter(&stubSSOService{err: pkgErrors.WrapError(pkgErrors.ErrResourceExists, "租户已配置身份提供方")})
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/admin/sso/providers", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Empty(t, auditService.entries)
+	})
+}
+
+func TestAdminSSOHandler_UpdateProvider(t *testing.T) {
+	provider := &models.SSOProvider{Tenant: "acme", Name: "Acme", Issuer: "https://login.acme.com", ClientID: "cloudpan", IsActive: true}
+	provider.ID = 5
+	router, auditService := setupAdminSSORouter(&stubSSOService{providers: map[uint]*models.SSOProvider{5: provider}})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, "/admin/sso/providers/5",
+		strings.NewReader(`{"name":"Acme","issuer":"https://login.acme.com","client_id":"cloudpan","enforce_sso":true}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Len(t, auditService.entries, 1)
+	entry := auditService.entries[0]
+	assert.Equal(t, false, entry.Before["enforce_sso"])
+	assert.Equal(t, true, entry.After["enforce_sso"])
+	assert.Equal(t, false, entry.After["client_secret_changed"])
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPut, "/admin/sso/providers/9",
+		strings.NewReader(`{"name":"Acme","issuer":"https://login.acme.com","client_id":"cloudpan"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestAdminSSOHandler_IssueSCIMToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	auditService := &recordingAuditService{}
+	handler := NewAdminSSOHandler(&stubSSOService{}, zap.NewNop())
+	handler.SetSCIMService(&stubSCIMService{})
+	handler.SetAuditService(auditService)
+	router.POST("/admin/sso/providers/:id/scim-token", func(c *gin.Context) {
+		c.Set("user_id", uint64(1))
+	}, handler.IssueSCIMToken)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/sso/providers/3/scim-token", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"token":"scim_good"`, "明文令牌只在签发时返回")
+	require.Len(t, auditService.entries, 1)
+	entry := auditService.entries[0]
+	assert.Equal(t, audit.ActionSCIMTokenChange, entry.Action)
+	assert.Equal(t, "3", entry.TargetID)
+	assert.Equal(t, map[string]interface{}{"token_prefix": "scim_go"}, entry.After, "审计日志不记录明文令牌")
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/sso/providers/9/scim-token", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Len(t, auditService.entries, 1)
+}
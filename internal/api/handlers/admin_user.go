@@ -0,0 +1,422 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/cache"
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+	"cloudpan/internal/service/audit"
+	"cloudpan/internal/service/user"
+)
+
+// 用户列表分页参数
+const (
+	defaultAdminUserPageSize = 20
+	maxAdminUserPageSize     = 100
+)
+
+// AdminUserHandler 管理员用户管理处理器
+type AdminUserHandler struct {
+	users         user.UserService
+	resets        user.PasswordResetService
+	tokens        cache.TokenStore
+	refreshTokens cache.RefreshTokenStore
+	audit         audit.AdminAuditService
+	inactivity    user.InactivityService
+	logger        *zap.Logger
+}
+
+// NewAdminUserHandler 创建管理员用户管理处理器
+func NewAdminUserHandler(users user.UserService, resets user.PasswordResetService, logger *zap.Logger) *AdminUserHandler {
+	return &AdminUserHandler{
+		users:  users,
+		resets: resets,
+		logger: logger,
+	}
+}
+
+// SetAuditService 设置管理员操作审计服务，未设置时不记录审计日志
+func (h *AdminUserHandler) SetAuditService(service audit.AdminAuditService) {
+	h.audit = service
+}
+
+// SetTokenStores 设置令牌吊销存储，暂停用户时吊销其已签发的令牌；未设置时只修改用户状态
+func (h *AdminUserHandler) SetTokenStores(tokens cache.TokenStore, refreshTokens cache.RefreshTokenStore) {
+	h.tokens = tokens
+	h.refreshTokens = refreshTokens
+}
+
+// SetInactivityService 设置长期未登录账户处置服务，未设置时查询长期未登录用户返回服务不可用
+func (h *AdminUserHandler) SetInactivityService(service user.InactivityService) {
+	h.inactivity = service
+}
+
+// SuspendUserRequest 暂停用户请求
+type SuspendUserRequest struct {
+	Reason string `json:"reason" binding:"max=500"` // 暂停原因，记录在审计日志中
+}
+
+// AdminPasswordResetRequest 强制单个用户重置密码请求
+type AdminPasswordResetRequest struct {
+	Reason string `json:"reason" binding:"max=500"` // 重置原因，显示在重置邮件中
+}
+
+// UpdateQuotaRequest 调整存储配额请求
+type UpdateQuotaRequest struct {
+	StorageQuota *int64 `json:"storage_quota" binding:"required,min=0"` // 存储配额(字节)，0表示不限制
+	Reason       string `json:"reason" binding:"max=500"`               // 调整原因，记录在审计日志中
+}
+
+// ListUsers 分页查询用户
+//
+// @Summary 查询用户列表
+// @Description 分页查询用户，指定关键字时按邮箱、用户名和显示名称模糊搜索
+// @Tags 系统
+// @Produce json
+// @Security BearerAuth
+// @Param keyword query string false "搜索关键字"
+// @Param page query int false "页码，默认1"
+// @Param page_size query int false "每页记录数，默认20，最大100"
+// @Success 200 {object} utils.Response{data=[]models.User} "查询成功"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "需要管理员权限"
+// @Failure 500 {object} utils.Response "内部服务器错误"
+// @Router /api/v1/admin/users [get]
+func (h *AdminUserHandler) ListUsers(c *gin.Context) {
+	page, _ := strconv.Atoi(c.Query("page"))
+	if page < 1 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(c.Query("page_size"))
+	if pageSize < 1 {
+		pageSize = defaultAdminUserPageSize
+	}
+	pageSize = min(pageSize, maxAdminUserPageSize)
+
+	users, total, err := h.users.SearchUsers(c.Request.Context(), strings.TrimSpace(c.Query("keyword")), pageSize, (page-1)*pageSize)
+	if err != nil {
+		respondServiceError(c, err, "查询用户失败")
+		return
+	}
+
+	utils.SuccessList(c, users, utils.NewPagination(page, pageSize, total))
+}
+
+// ListInactiveUsers 分页查询长期未登录的用户
+//
+// @Summary 查询长期未登录的用户
+// @Description 分页查询处于提醒期或已被长期未登录策略处置的用户，按下一步的执行时间升序。
+// @Description step为下一步：action降低配额或冻结，deletion计划删除，done全部已执行；用户重新登录后记录被清除
+// @Tags 系统
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "页码，默认1"
+// @Param page_size query int false "每页记录数，默认20，最大100"
+// @Success 200 {object} utils.Response{data=[]models.UserInactivity} "查询成功"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "需要管理员权限"
+// @Failure 503 {object} utils.Response "未启用长期未登录策略"
+// @Router /api/v1/admin/users/inactive [get]
+func (h *AdminUserHandler) ListInactiveUsers(c *gin.Context) {
+	if h.inactivity == nil {
+		utils.ErrorWithMessage(c, utils.CodeServiceUnavailable, "未启用长期未登录策略")
+		return
+	}
+
+	page, _ := strconv.Atoi(c.Query("page"))
+	if page < 1 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(c.Query("page_size"))
+	if pageSize < 1 {
+		pageSize = defaultAdminUserPageSize
+	}
+	pageSize = min(pageSize, maxAdminUserPageSize)
+
+	states, total, err := h.inactivity.ListStates(c.Request.Context(), page, pageSize)
+	if err != nil {
+		respondServiceError(c, err, "查询长期未登录用户失败")
+		return
+	}
+	utils.SuccessList(c, states, utils.NewPagination(page, pageSize, total))
+}
+
+// GetUser 查询用户详情
+//
+// @Summary 查询用户详情
+// @Tags 系统
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "用户ID"
+// @Success 200 {object} utils.Response{data=models.User} "查询成功"
+// @Failure 400 {object} utils.Response "用户ID无效"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "需要管理员权限"
+// @Failure 404 {object} utils.Response "用户不存在"
+// @Router /api/v1/admin/users/{id} [get]
+func (h *AdminUserHandler) GetUser(c *gin.Context) {
+	target, ok := h.loadUser(c)
+	if !ok {
+		return
+	}
+	utils.Success(c, target)
+}
+
+// SuspendUser 暂停用户
+//
+// @Summary 暂停用户
+// @Description 暂停用户账户并吊销其已签发的全部令牌，暂停期间用户无法登录。管理员不能暂停自己
+// @Tags 系统
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "用户ID"
+// @Param request body SuspendUserRequest false "暂停原因"
+// @Success 200 {object} utils.Response "暂停成功"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "需要管理员权限或不能暂停自己"
+// @Failure 404 {object} utils.Response "用户不存在"
+// @Router /api/v1/admin/users/{id}/suspend [post]
+func (h *AdminUserHandler) SuspendUser(c *gin.Context) {
+	adminID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	var req SuspendUserRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+			return
+		}
+	}
+
+	target, ok := h.loadUser(c)
+	if !ok {
+		return
+	}
+	if target.ID == adminID {
+		utils.ErrorWithMessage(c, utils.CodeForbidden, "不能暂停自己的账户")
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.users.SuspendUser(ctx, target.ID, req.Reason); err != nil {
+		respondUserError(c, err, "暂停用户失败")
+		return
+	}
+
+	// 状态已修改，令牌吊销失败只记录日志，登录时仍会拒绝暂停的用户
+	if h.tokens != nil {
+		if err := h.tokens.RevokeUserTokens(ctx, uint64(target.ID), time.Now()); err != nil {
+			h.logger.Warn("Failed to revoke tokens of suspended user", zap.Uint("user_id", target.ID), zap.Error(err))
+		}
+	}
+	if h.refreshTokens != nil {
+		if err := h.refreshTokens.RevokeAllForUser(ctx, uint64(target.ID)); err != nil {
+			h.logger.Warn("Failed to revoke refresh tokens of suspended user", zap.Uint("user_id", target.ID), zap.Error(err))
+		}
+	}
+
+	h.logger.Warn("User suspended by admin",
+		zap.Uint("admin_id", adminID),
+		zap.Uint("user_id", target.ID),
+		zap.String("ip", c.ClientIP()))
+
+	recordAdminAudit(c, h.audit, h.logger, &audit.Entry{
+		Action:     audit.ActionUserSuspend,
+		TargetType: audit.TargetUser,
+		TargetID:   strconv.FormatUint(uint64(target.ID), 10),
+		Before:     map[string]interface{}{"status": target.Status},
+		After:      map[string]interface{}{"status": "suspended"},
+		Reason:     req.Reason,
+	})
+
+	utils.SuccessWithMessage(c, "用户已暂停", nil)
+}
+
+// ActivateUser 激活用户
+//
+// @Summary 激活用户
+// @Description 将暂停或停用的用户恢复为正常状态
+// @Tags 系统
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "用户ID"
+// @Success 200 {object} utils.Response "激活成功"
+// @Failure 400 {object} utils.Response "用户ID无效"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "需要管理员权限或账户已计划删除"
+// @Failure 404 {object} utils.Response "用户不存在"
+// @Router /api/v1/admin/users/{id}/activate [post]
+func (h *AdminUserHandler) ActivateUser(c *gin.Context) {
+	target, ok := h.loadUser(c)
+	if !ok {
+		return
+	}
+	// 计划删除的账户由用户本人取消删除，避免绕过删除流程
+	if target.Status == "deleted" {
+		utils.ErrorWithMessage(c, utils.CodeForbidden, "账户已计划删除，不能直接激活")
+		return
+	}
+
+	if err := h.users.ActivateUser(c.Request.Context(), target.ID); err != nil {
+		respondUserError(c, err, "激活用户失败")
+		return
+	}
+
+	recordAdminAudit(c, h.audit, h.logger, &audit.Entry{
+		Action:     audit.ActionUserActivate,
+		TargetType: audit.TargetUser,
+		TargetID:   strconv.FormatUint(uint64(target.ID), 10),
+		Before:     map[string]interface{}{"status": target.Status},
+		After:      map[string]interface{}{"status": "active"},
+	})
+
+	utils.SuccessWithMessage(c, "用户已激活", nil)
+}
+
+// ResetUserPassword 强制用户重置密码
+//
+// @Summary 强制用户重置密码
+// @Description 标记用户必须重置密码、吊销已签发的全部令牌并发送重置邮件，等同于只包含一个用户的批量重置任务
+// @Tags 系统
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "用户ID"
+// @Param request body AdminPasswordResetRequest false "重置原因"
+// @Success 200 {object} utils.Response{data=user.PasswordResetJob} "任务已创建"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "需要管理员权限"
+// @Failure 404 {object} utils.Response "用户不存在"
+// @Router /api/v1/admin/users/{id}/password-reset [post]
+func (h *AdminUserHandler) ResetUserPassword(c *gin.Context) {
+	adminID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	var req AdminPasswordResetRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+			return
+		}
+	}
+
+	target, ok := h.loadUser(c)
+	if !ok {
+		return
+	}
+
+	job, err := h.resets.StartBulkReset(c.Request.Context(), adminID, user.BulkPasswordResetRequest{
+		UserIDs: []uint{target.ID},
+		Reason:  req.Reason,
+	})
+	if err != nil {
+		respondServiceError(c, err, "创建重置任务失败")
+		return
+	}
+
+	h.logger.Warn("Password reset forced by admin",
+		zap.Uint("admin_id", adminID),
+		zap.Uint("user_id", target.ID),
+		zap.String("job_id", job.ID),
+		zap.String("ip", c.ClientIP()))
+
+	recordAdminAudit(c, h.audit, h.logger, &audit.Entry{
+		Action:     audit.ActionUserPasswordReset,
+		TargetType: audit.TargetUser,
+		TargetID:   strconv.FormatUint(uint64(target.ID), 10),
+		After:      map[string]interface{}{"must_reset_password": true, "job_id": job.ID},
+		Reason:     req.Reason,
+	})
+
+	utils.Success(c, job)
+}
+
+// UpdateUserQuota 调整用户存储配额
+//
+// @Summary 调整存储配额
+// @Description 设置用户的存储配额(字节)，0表示不限制。配额可以低于已用空间，此时用户不能继续上传
+// @Tags 系统
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "用户ID"
+// @Param request body UpdateQuotaRequest true "存储配额和原因"
+// @Success 200 {object} utils.Response "调整成功"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "需要管理员权限"
+// @Failure 404 {object} utils.Response "用户不存在"
+// @Router /api/v1/admin/users/{id}/quota [put]
+func (h *AdminUserHandler) UpdateUserQuota(c *gin.Context) {
+	var req UpdateQuotaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+		return
+	}
+
+	target, ok := h.loadUser(c)
+	if !ok {
+		return
+	}
+
+	if err := h.users.UpdateStorageQuota(c.Request.Context(), target.ID, *req.StorageQuota); err != nil {
+		respondUserError(c, err, "调整存储配额失败")
+		return
+	}
+
+	recordAdminAudit(c, h.audit, h.logger, &audit.Entry{
+		Action:     audit.ActionUserQuotaChange,
+		TargetType: audit.TargetUser,
+		TargetID:   strconv.FormatUint(uint64(target.ID), 10),
+		Before:     map[string]interface{}{"storage_quota": target.StorageQuota, "storage_used": target.StorageUsed},
+		After:      map[string]interface{}{"storage_quota": *req.StorageQuota},
+		Reason:     req.Reason,
+	})
+
+	utils.SuccessWithMessage(c, "存储配额已调整", gin.H{
+		"storage_quota": *req.StorageQuota,
+		"storage_used":  target.StorageUsed,
+	})
+}
+
+// loadUser 按路径中的用户ID获取用户，失败时已写入响应
+func (h *AdminUserHandler) loadUser(c *gin.Context) (*models.User, bool) {
+	userID, ok := parseIDParam(c, "id")
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "用户ID无效")
+		return nil, false
+	}
+
+	target, err := h.users.GetUserByID(c.Request.Context(), userID)
+	if err != nil {
+		respondUserError(c, err, "获取用户失败")
+		return nil, false
+	}
+	return target, true
+}
+
+// respondUserError 用户服务错误响应，用户不存在时返回404
+func respondUserError(c *gin.Context, err error, fallbackMessage string) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.ErrorWithMessage(c, utils.CodeNotFound, "用户不存在")
+		return
+	}
+	respondServiceError(c, err, fallbackMessage)
+}
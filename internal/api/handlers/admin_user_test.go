@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/cache"
+	"cloudpan/internal/repository/models"
+	"cloudpan/internal/service/audit"
+	"cloudpan/internal/service/user"
+)
+
+func setupAdminUserRouter(users *MockUserService, resets *MockPasswordResetService, tokens cache.TokenStore, auditService audit.AdminAuditService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler := NewAdminUserHandler(users, resets, zap.NewNop())
+	handler.SetAuditService(auditService)
+	handler.SetTokenStores(tokens, nil)
+	admin := router.Group("/admin/users", func(c *gin.Context) {
+		c.Set("user_id", uint64(1))
+	})
+	admin.GET("", handler.ListUsers)
+	admin.GET("/:id", handler.GetUser)
+	admin.POST("/:id/suspend", handler.SuspendUser)
+	admin.POST("/:id/activate", handler.ActivateUser)
+	admin.POST("/:id/password-reset", handler.ResetUserPassword)
+	admin.PUT("/:id/quota", handler.UpdateUserQuota)
+	return router
+}
+
+func adminTestUser(id uint, status string) *models.User {
+	u := &models.User{Status: status}
+	u.ID = id
+	return u
+}
+
+func serveAdminUser(router *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAdminUserHandler_ListUsers(t *testing.T) {
+	users := new(MockUserService)
+	users.On("SearchUsers", mock.Anything, "alice", 100, 100).
+		Return([]*models.User{{Username: "alice", PasswordHash: "secret-hash"}}, int64(101), nil)
+	router := setupAdminUserRouter(users, nil, nil, nil)
+
+	// 每页记录数超过上限时按上限查询
+	w := serveAdminUser(router, http.MethodGet, "/admin/users?keyword=+alice+&page=2&page_size=500", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"username":"alice"`)
+	assert.Contains(t, w.Body.String(), `"total_count":101`)
+	assert.NotContains(t, w.Body.String(), "secret-hash")
+	users.AssertExpectations(t)
+}
+
+func TestAdminUserHandler_SuspendUser(t *testing.T) {
+	t.Run("suspends and revokes tokens", func(t *testing.T) {
+		users := new(MockUserService)
+		users.On("GetUserByID", mock.Anything, uint(7)).Return(adminTestUser(7, "active"), nil)
+		users.On("SuspendUser", mock.Anything, uint(7), "滥用").Return(nil)
+		tokens := cache.NewMemoryTokenStore(time.Hour)
+		recorder := &recordingAuditService{}
+		router := setupAdminUserRouter(users, nil, tokens, recorder)
+
+		w := serveAdminUser(router, http.MethodPost, "/admin/users/7/suspend", `{"reason":"滥用"}`)
+		require.Equal(t, http.StatusOK, w.Code)
+		users.AssertExpectations(t)
+
+		revokedBefore, err := tokens.RevokedBefore(context.Background(), 7)
+		require.NoError(t, err)
+		assert.False(t, revokedBefore.IsZero())
+
+		require.Len(t, recorder.entries, 1)
+		assert.Equal(t, audit.ActionUserSuspend, recorder.entries[0].Action)
+		assert.Equal(t, "7", recorder.entries[0].TargetID)
+		assert.Equal(t, "active", recorder.entries[0].Before["status"])
+		assert.Equal(t, "滥用", recorder.entries[0].Reason)
+	})
+
+	t.Run("cannot suspend self", func(t *testing.T) {
+		users := new(MockUserService)
+		users.On("GetUserByID", mock.Anything, uint(1)).Return(adminTestUser(1, "active"), nil)
+		router := setupAdminUserRouter(users, nil, nil, nil)
+
+		w := serveAdminUser(router, http.MethodPost, "/admin/users/1/suspend", "")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		users.AssertNotCalled(t, "SuspendUser", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("user not found", func(t *testing.T) {
+		users := new(MockUserService)
+		users.On("GetUserByID", mock.Anything, uint(9)).Return(nil, fmt.Errorf("获取用户失败: %w", gorm.ErrRecordNotFound))
+		router := setupAdminUserRouter(users, nil, nil, nil)
+
+		w := serveAdminUser(router, http.MethodPost, "/admin/users/9/suspend", "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestAdminUserHandler_ActivateUser(t *testing.T) {
+	users := new(MockUserService)
+	users.On("GetUserByID", mock.Anything, uint(7)).Return(adminTestUser(7, "suspended"), nil)
+	users.On("GetUserByID", mock.Anything, uint(8)).Return(adminTestUser(8, "deleted"), nil)
+	users.On("ActivateUser", mock.Anything, uint(7)).Return(nil)
+	recorder := &recordingAuditService{}
+	router := setupAdminUserRouter(users, nil, nil, recorder)
+
+	w := serveAdminUser(router, http.MethodPost, "/admin/users/7/activate", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Len(t, recorder.entries, 1)
+	assert.Equal(t, audit.ActionUserActivate, recorder.entries[0].Action)
+
+	// 计划删除的账户不能由管理员直接激活
+	w = serveAdminUser(router, http.MethodPost, "/admin/users/8/activate", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	users.AssertNotCalled(t, "ActivateUser", mock.Anything, uint(8))
+}
+
+func TestAdminUserHandler_ResetUserPassword(t *testing.T) {
+	users := new(MockUserService)
+	users.On("GetUserByID", mock.Anything, uint(7)).Return(adminTestUser(7, "active"), nil)
+	resets := new(MockPasswordResetService)
+	resets.On("StartBulkReset", mock.Anything, uint(1), user.BulkPasswordResetRequest{UserIDs: []uint{7}, Reason: "凭据泄露"}).
+		Return(&user.PasswordResetJob{ID: "job1", Status: user.PasswordResetJobRunning, Total: 1}, nil)
+	recorder := &recordingAuditService{}
+	router := setupAdminUserRouter(users, resets, nil, recorder)
+
+	w := serveAdminUser(router, http.MethodPost, "/admin/users/7/password-reset", `{"reason":"凭据泄露"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"job1"`)
+	resets.AssertExpectations(t)
+	require.Len(t, recorder.entries, 1)
+	assert.Equal(t, audit.ActionUserPasswordReset, recorder.entries[0].Action)
+}
+
+func TestAdminUserHandler_UpdateUserQuota(t *testing.T) {
+	quotaUser := adminTestUser(7, "active")
+	quotaUser.StorageQuota, quotaUser.StorageUsed = 1024, 512
+	users := new(MockUserService)
+	users.On("GetUserByID", mock.Anything, uint(7)).Return(quotaUser, nil)
+	users.On("UpdateStorageQuota", mock.Anything, uint(7), int64(0)).Return(nil)
+	recorder := &recordingAuditService{}
+	router := setupAdminUserRouter(users, nil, nil, recorder)
+
+	// 缺少配额或配额为负数时拒绝
+	w := serveAdminUser(router, http.MethodPut, "/admin/users/7/quota", `{}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = serveAdminUser(router, http.MethodPut, "/admin/users/7/quota", `{"storage_quota":-1}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// 0表示不限制
+	w = serveAdminUser(router, http.MethodPut, "/admin/users/7/quota", `{"storage_quota":0,"reason":"VIP"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	users.AssertExpectations(t)
+	require.Len(t, recorder.entries, 1)
+	assert.Equal(t, audit.ActionUserQuotaChange, recorder.entries[0].Action)
+	assert.Equal(t, int64(1024), recorder.entries[0].Before["storage_quota"])
+}
+
+func TestAdminUserHandler_ListInactiveUsers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	newRouter := func(inactivity user.InactivityService) *gin.Engine {
+		router := gin.New()
+		handler := NewAdminUserHandler(new(MockUserService), nil, zap.NewNop())
+		if inactivity != nil {
+			handler.SetInactivityService(inactivity)
+		}
+		router.GET("/admin/users/inactive", handler.ListInactiveUsers)
+		return router
+	}
+
+	w := serveAdminUser(newRouter(nil), http.MethodGet, "/admin/users/inactive", "")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	due := time.Date(2024, 7, 31, 0, 0, 0, 0, time.UTC)
+	inactivity := &stubInactivityService{states: []*models.UserInactivity{
+		{UserID: 7, Step: models.InactivityStepAction, StepDueAt: &due, WarningsSent: 1, User: &models.User{Username: "alice", PasswordHash: "secret-hash"}},
+	}}
+	w = serveAdminUser(newRouter(inactivity), http.MethodGet, "/admin/users/inactive?page=2&page_size=500", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []int{2, 100}, inactivity.page)
+	assert.Contains(t, w.Body.String(), `"step":"action"`)
+	assert.Contains(t, w.Body.String(), `"username":"alice"`)
+	assert.NotContains(t, w.Body.String(), "secret-hash")
+
+	inactivity.err = fmt.Errorf("database unavailable")
+	w = serveAdminUser(newRouter(inactivity), http.MethodGet, "/admin/users/inactive", "")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
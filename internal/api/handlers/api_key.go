@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+	"cloudpan/internal/service/audit"
+	"cloudpan/internal/service/user"
+)
+
+// APIKeyHandler 用户API密钥处理器
+type APIKeyHandler struct {
+	securityAudit
+	service user.APIKeyService
+	logger  *zap.Logger
+}
+
+// NewAPIKeyHandler 创建用户API密钥处理器
+func NewAPIKeyHandler(service user.APIKeyService, logger *zap.Logger) *APIKeyHandler {
+	return &APIKeyHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// CreateAPIKeyRequest 创建API密钥请求
+type CreateAPIKeyRequest struct {
+	// 密钥名称，用于区分不同的脚本或工具
+	Name string `json:"name" binding:"required,max=100" example:"nightly-backup"`
+	// 权限范围：read查询和下载，write上传、修改和删除，share创建和管理分享
+	Scopes []string `json:"scopes" binding:"required,min=1,dive,oneof=read write share" example:"read,write"`
+	// 有效天数，为0或不传表示不过期(配置了最长有效期时必填)
+	ExpiresInDays int `json:"expires_in_days" binding:"min=0" example:"90"`
+}
+
+// APIKeyInfo API密钥信息，不包含密钥明文
+type APIKeyInfo struct {
+	ID         uint       `json:"id" example:"3"`
+	Name       string     `json:"name" example:"nightly-backup"`
+	Prefix     string     `json:"prefix" example:"cpk_8Hq2ZkP1"`            // 密钥前缀，用于识别密钥
+	Scopes     []string   `json:"scopes" example:"read,write"`              // 权限范围
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`                     // 过期时间，为空表示不过期
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`                   // 最后使用时间
+	LastUsedIP *string    `json:"last_used_ip,omitempty" example:"1.2.3.4"` // 最后使用的IP地址
+	CreatedAt  time.Time  `json:"created_at"`                               // 创建时间
+}
+
+// CreatedAPIKeyInfo 新创建的API密钥，密钥明文只返回这一次
+type CreatedAPIKeyInfo struct {
+	APIKeyInfo
+	// 密钥明文，请求时放在 X-API-Key 请求头中
+	Key string `json:"key" example:"cpk_8Hq2ZkP1mW7xYc3Nv9LbT4sDfG6hJ0kRaE5uQ1oI"`
+}
+
+// ListAPIKeys 列出当前用户的API密钥
+//
+// @Summary 列出API密钥
+// @Description 列出当前用户的全部API密钥，不返回密钥明文
+// @Tags 用户
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response{data=[]APIKeyInfo} "查询成功"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 500 {object} utils.Response "内部服务器错误"
+// @Router /api/v1/users/me/api-keys [get]
+func (h *APIKeyHandler) ListAPIKeys(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	keys, err := h.service.List(c.Request.Context(), userID)
+	if err != nil {
+		h.logger.Error("Failed to list API keys", zap.Uint("user_id", userID), zap.Error(err))
+		respondServiceError(c, err, "查询API密钥失败")
+		return
+	}
+	infos := make([]APIKeyInfo, 0, len(keys))
+	for _, key := range keys {
+		infos = append(infos, newAPIKeyInfo(key))
+	}
+	utils.Success(c, infos)
+}
+
+// CreateAPIKey 创建API密钥
+//
+// @Summary 创建API密钥
+// @Description 创建用于脚本和第三方工具的API密钥，请求时放在 X-API-Key 请求头中代替登录令牌。
+// @Description 密钥明文只在本次响应中返回，服务端只保存哈希。API密钥不能访问管理接口，也不能修改密码、两步验证和API密钥等账户安全设置；
+// @Description 每个密钥单独限流，超过时返回429
+// @Tags 用户
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body CreateAPIKeyRequest true "密钥名称、权限范围和有效期"
+// @Success 200 {object} utils.Response{data=CreatedAPIKeyInfo} "创建成功"
+// @Failure 400 {object} utils.Response "参数错误或有效期超过上限"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "密钥数量已达上限、使用API密钥或模拟登录中"
+// @Failure 500 {object} utils.Response "内部服务器错误"
+// @Router /api/v1/users/me/api-keys [post]
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+		return
+	}
+
+	created, err := h.service.Create(c.Request.Context(), userID, &user.CreateAPIKeyRequest{
+		Name:   req.Name,
+		Scopes: req.Scopes,
+		TTL:    time.Duration(req.ExpiresInDays) * 24 * time.Hour,
+	})
+	if err != nil {
+		h.logger.Warn("Failed to create API key", zap.Uint("user_id", userID), zap.Error(err))
+		respondServiceError(c, err, "创建API密钥失败")
+		return
+	}
+
+	info := newAPIKeyInfo(created.APIKey)
+	h.recordSecurity(c, h.logger, &audit.Event{
+		UserID:       userID,
+		Action:       audit.SecurityActionAPIKeyCreate,
+		ResourceType: audit.ResourceAPIKey,
+		ResourceID:   strconv.FormatUint(uint64(info.ID), 10),
+		ResourceName: info.Name,
+		After:        map[string]interface{}{"prefix": info.Prefix, "scopes": info.Scopes, "expires_at": info.ExpiresAt},
+	})
+	utils.Success(c, CreatedAPIKeyInfo{APIKeyInfo: info, Key: created.Key})
+}
+
+// RevokeAPIKey 撤销API密钥
+//
+// @Summary 撤销API密钥
+// @Description 撤销当前用户的API密钥，撤销后使用该密钥的请求立即失败
+// @Tags 用户
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "API密钥ID"
+// @Success 200 {object} utils.Response "撤销成功"
+// @Failure 400 {object} utils.Response "密钥ID格式错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "使用API密钥或模拟登录中"
+// @Failure 404 {object} utils.Response "密钥不存在"
+// @Failure 500 {object} utils.Response "内部服务器错误"
+// @Router /api/v1/users/me/api-keys/{id} [delete]
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+	keyID, ok := parseIDParam(c, "id")
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "密钥ID格式错误")
+		return
+	}
+
+	key, err := h.service.Revoke(c.Request.Context(), userID, keyID)
+	if err != nil {
+		respondServiceError(c, err, "撤销API密钥失败")
+		return
+	}
+
+	h.recordSecurity(c, h.logger, &audit.Event{
+		UserID:       userID,
+		Action:       audit.SecurityActionAPIKeyRevoke,
+		ResourceType: audit.ResourceAPIKey,
+		ResourceID:   strconv.FormatUint(uint64(key.ID), 10),
+		ResourceName: key.Name,
+		Before:       map[string]interface{}{"prefix": key.Prefix, "scopes": key.ScopeList()},
+	})
+	utils.SuccessWithMessage(c, "API密钥已撤销", nil)
+}
+
+// newAPIKeyInfo 转换为不含密钥哈希的响应
+func newAPIKeyInfo(key *models.APIKey) APIKeyInfo {
+	return APIKeyInfo{
+		ID:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     key.ScopeList(),
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+		LastUsedIP: key.LastUsedIP,
+		CreatedAt:  key.CreatedAt,
+	}
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+	"cloudpan/internal/service/audit"
+	"cloudpan/internal/service/user"
+)
+
+// stubAPIKeyService 内存API密钥服务
+type stubAPIKeyService struct {
+	keys []*models.APIKey
+	ttl  time.Duration
+}
+
+func (s *stubAPIKeyService) Create(_ context.Context, userID uint, req *user.CreateAPIKeyRequest) (*user.CreatedAPIKey, error) {
+	s.ttl = req.TTL
+	key := &models.APIKey{UserID: userID, Name: req.Name, Prefix: "cpk_abcdefgh", KeyHash: "hash", Scopes: "read,share"}
+	key.ID = uint(len(s.keys) + 1)
+	s.keys = append(s.keys, key)
+	return &user.CreatedAPIKey{Key: "cpk_abcdefghsecret", APIKey: key}, nil
+}
+
+func (s *stubAPIKeyService) List(context.Context, uint) ([]*models.APIKey, error) {
+	return s.keys, nil
+}
+
+func (s *stubAPIKeyService) Revoke(_ context.Context, userID, id uint) (*models.APIKey, error) {
+	for i, key := range s.keys {
+		if key.ID == id && key.UserID == userID {
+			s.keys = append(s.keys[:i], s.keys[i+1:]...)
+			return key, nil
+		}
+	}
+	return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "API密钥不存在")
+}
+
+func (s *stubAPIKeyService) Authenticate(context.Context, string, string) (*models.APIKey, *models.User, error) {
+	return nil, nil, pkgErrors.ErrPermissionDenied
+}
+
+func TestAPIKeyHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	service := &stubAPIKeyService{}
+	security := &recordingSecurityAudit{}
+	handler := NewAPIKeyHandler(service, zap.NewNop())
+	handler.SetSecurityAuditService(security)
+
+	call := func(handle gin.HandlerFunc, method, id string, body interface{}) (*httptest.ResponseRecorder, utils.Response) {
+		reqBody, _ := json.Marshal(body)
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(method, "/api/v1/users/me/api-keys", bytes.NewBuffer(reqBody))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Params = gin.Params{{Key: "id", Value: id}}
+		c.Set("user_id", uint64(1))
+		handle(c)
+		return w, decodeShareResponse(t, w)
+	}
+
+	w, _ := call(handler.CreateAPIKey, http.MethodPost, "", gin.H{"name": "ci", "scopes": []string{"admin"}})
+	assert.Equal(t, http.StatusBadRequest, w.Code, "不支持的权限范围")
+	w, _ = call(handler.CreateAPIKey, http.MethodPost, "", gin.H{"name": "ci"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w, resp := call(handler.CreateAPIKey, http.MethodPost, "", CreateAPIKeyRequest{Name: "ci", Scopes: []string{"read", "share"}, ExpiresInDays: 30})
+	require.Equal(t, http.StatusOK, w.Code)
+	data, ok := resp.Data.(map[string]interface{})
+	require.True(t, ok)
+	assert.Equal(t, "cpk_abcdefghsecret", data["key"])
+	assert.Equal(t, []interface{}{"read", "share"}, data["scopes"])
+	assert.NotContains(t, w.Body.String(), "hash")
+	assert.Equal(t, 30*24*time.Hour, service.ttl)
+
+	w, resp = call(handler.ListAPIKeys, http.MethodGet, "", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	list, ok := resp.Data.([]interface{})
+	require.True(t, ok)
+	require.Len(t, list, 1)
+	assert.NotContains(t, list[0], "key", "列表不返回密钥明文")
+
+	w, _ = call(handler.RevokeAPIKey, http.MethodDelete, "abc", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w, _ = call(handler.RevokeAPIKey, http.MethodDelete, "9", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w, _ = call(handler.RevokeAPIKey, http.MethodDelete, "1", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, service.keys)
+
+	require.Len(t, security.events, 2)
+	assert.Equal(t, audit.SecurityActionAPIKeyCreate, security.events[0].Action)
+	assert.Equal(t, audit.SecurityActionAPIKeyRevoke, security.events[1].Action)
+	assert.Equal(t, "1", security.events[1].ResourceID)
+}
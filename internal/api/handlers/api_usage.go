@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/service/usage"
+)
+
+// defaultUsageReportDays 未指定日期范围时报表覆盖的天数(包含当天)
+const defaultUsageReportDays = 30
+
+// APIUsageHandler API用量报表处理器
+type APIUsageHandler struct {
+	usageService usage.UsageService
+	logger       *zap.Logger
+	now          func() time.Time
+}
+
+// NewAPIUsageHandler 创建API用量报表处理器
+func NewAPIUsageHandler(usageService usage.UsageService, logger *zap.Logger) *APIUsageHandler {
+	return &APIUsageHandler{
+		usageService: usageService,
+		logger:       logger,
+		now:          time.Now,
+	}
+}
+
+// GetMyUsage 获取当前用户的API用量报表
+//
+// @Summary 获取API用量报表
+// @Description 返回日期范围内的请求数、失败数、流量合计，按日用量、调用最多的接口和按API密钥用量。日期按UTC划分，默认最近30天。配置了计量套餐时仅计量套餐用户可用
+// @Tags 用户
+// @Produce json
+// @Security BearerAuth
+// @Param from query string false "起始日期(YYYY-MM-DD，包含)"
+// @Param to query string false "结束日期(YYYY-MM-DD，包含)，默认今天"
+// @Success 200 {object} utils.Response{data=usage.Report} "获取成功"
+// @Failure 400 {object} utils.Response "日期格式或范围错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "当前套餐不提供API用量报表"
+// @Failure 500 {object} utils.Response "内部服务器错误"
+// @Router /api/v1/users/me/usage [get]
+func (h *APIUsageHandler) GetMyUsage(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	from, to, ok := h.parseDateRange(c)
+	if !ok {
+		return
+	}
+
+	report, err := h.usageService.GetReport(c.Request.Context(), userID, from, to)
+	if err != nil {
+		h.logger.Warn("Failed to build API usage report",
+			zap.Uint("user_id", userID),
+			zap.Error(err),
+			zap.String("ip", c.ClientIP()))
+		respondServiceError(c, err, "获取API用量失败")
+		return
+	}
+
+	utils.Success(c, report)
+}
+
+// ExportMyUsage 以CSV格式导出当前用户的API用量明细
+//
+// @Summary 导出API用量
+// @Description 导出日期范围内按 日期+API密钥+接口 汇总的用量明细，列为date,api_key_id,endpoint,requests,errors,bytes_in,bytes_out。api_key_id为0表示登录会话发起的请求
+// @Tags 用户
+// @Produce text/csv
+// @Security BearerAuth
+// @Param from query string false "起始日期(YYYY-MM-DD，包含)"
+// @Param to query string false "结束日期(YYYY-MM-DD，包含)，默认今天"
+// @Success 200 {file} file "CSV文件"
+// @Failure 400 {object} utils.Response "日期格式或范围错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "当前套餐不提供API用量报表"
+// @Failure 500 {object} utils.Response "内部服务器错误"
+// @Router /api/v1/users/me/usage/export [get]
+func (h *APIUsageHandler) ExportMyUsage(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	from, to, ok := h.parseDateRange(c)
+	if !ok {
+		return
+	}
+
+	// 先写入缓冲区，导出失败时仍可返回统一的错误响应
+	var buf bytes.Buffer
+	if err := h.usageService.ExportCSV(c.Request.Context(), userID, from, to, &buf); err != nil {
+		h.logger.Warn("Failed to export API usage",
+			zap.Uint("user_id", userID),
+			zap.Error(err),
+			zap.String("ip", c.ClientIP()))
+		respondServiceError(c, err, "导出API用量失败")
+		return
+	}
+
+	filename := fmt.Sprintf("api-usage_%s_%s.csv", from.Format(usage.DateLayout), to.Format(usage.DateLayout))
+	c.Header("Content-Disposition", contentDisposition(filename))
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Data(200, "text/csv; charset=utf-8", buf.Bytes())
+}
+
+// parseDateRange 解析from/to查询参数，默认最近30天
+func (h *APIUsageHandler) parseDateRange(c *gin.Context) (time.Time, time.Time, bool) {
+	today := h.now().UTC()
+	to, ok := parseUsageDate(c, "to", today)
+	if !ok {
+		return time.Time{}, time.Time{}, false
+	}
+	from, ok := parseUsageDate(c, "from", to.AddDate(0, 0, 1-defaultUsageReportDays))
+	if !ok {
+		return time.Time{}, time.Time{}, false
+	}
+	return from, to, true
+}
+
+// parseUsageDate 解析YYYY-MM-DD格式的日期参数，参数为空时返回默认值
+func parseUsageDate(c *gin.Context, name string, fallback time.Time) (time.Time, bool) {
+	value := c.Query(name)
+	if value == "" {
+		return fallback, true
+	}
+	date, err := time.Parse(usage.DateLayout, value)
+	if err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, fmt.Sprintf("%s日期格式错误，应为YYYY-MM-DD", name))
+		return time.Time{}, false
+	}
+	return date, true
+}
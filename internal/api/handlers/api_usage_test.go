@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/service/usage"
+)
+
+// MockUsageService 模拟API用量统计服务
+type MockUsageService struct {
+	mock.Mock
+}
+
+func (m *MockUsageService) RecordRequest(userID, apiKeyID uint, endpoint string, status int, bytesIn, bytesOut int64) {
+	m.Called(userID, apiKeyID, endpoint, status, bytesIn, bytesOut)
+}
+
+func (m *MockUsageService) Flush(ctx context.Context) error {
+	return m.Called(ctx).Error(0)
+}
+
+func (m *MockUsageService) GetReport(ctx context.Context, userID uint, from, to time.Time) (*usage.Report, error) {
+	args := m.Called(ctx, userID, from, to)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*usage.Report), args.Error(1)
+}
+
+func (m *MockUsageService) ExportCSV(ctx context.Context, userID uint, from, to time.Time, w io.Writer) error {
+	args := m.Called(ctx, userID, from, to, w)
+	if args.Error(0) == nil {
+		_, _ = io.WriteString(w, "date,api_key_id,endpoint,requests,errors,bytes_in,bytes_out\n")
+	}
+	return args.Error(0)
+}
+
+func setupAPIUsageRouter(service *MockUsageService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler := NewAPIUsageHandler(service, zap.NewNop())
+	handler.now = func() time.Time { return time.Date(2024, 3, 31, 18, 0, 0, 0, time.UTC) }
+	me := router.Group("/users/me", func(c *gin.Context) {
+		c.Set("user_id", uint64(7))
+	})
+	me.GET("/usage", handler.GetMyUsage)
+	me.GET("/usage/export", handler.ExportMyUsage)
+	return router
+}
+
+func TestAPIUsageHandler_GetMyUsage(t *testing.T) {
+	t.Run("defaults to last 30 days", func(t *testing.T) {
+		service := new(MockUsageService)
+		from := time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)
+		service.On("GetReport", mock.Anything, uint(7), mock.MatchedBy(func(t time.Time) bool {
+			return t.Format(usage.DateLayout) == from.Format(usage.DateLayout)
+		}), mock.MatchedBy(func(t time.Time) bool {
+			return t.Format(usage.DateLayout) == "2024-03-31"
+		})).Return(&usage.Report{From: "2024-03-02", To: "2024-03-31"}, nil)
+
+		w := httptest.NewRecorder()
+		setupAPIUsageRouter(service).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/me/usage", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		service.AssertExpectations(t)
+	})
+
+	t.Run("invalid date", func(t *testing.T) {
+		service := new(MockUsageService)
+		w := httptest.NewRecorder()
+		setupAPIUsageRouter(service).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/me/usage?from=2024/03/01", nil))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("not on metered plan", func(t *testing.T) {
+		service := new(MockUsageService)
+		service.On("GetReport", mock.Anything, uint(7), mock.Anything, mock.Anything).
+			Return(nil, pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, "当前套餐不提供API用量报表"))
+
+		w := httptest.NewRecorder()
+		setupAPIUsageRouter(service).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/me/usage", nil))
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Equal(t, utils.CodeForbidden, decodeShareResponse(t, w).Code)
+	})
+}
+
+func TestAPIUsageHandler_ExportMyUsage(t *testing.T) {
+	t.Run("csv attachment", func(t *testing.T) {
+		service := new(MockUsageService)
+		service.On("ExportCSV", mock.Anything, uint(7), time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC), mock.Anything).Return(nil)
+
+		w := httptest.NewRecorder()
+		setupAPIUsageRouter(service).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/me/usage/export?from=2024-03-01&to=2024-03-15", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Header().Get("Content-Disposition"), `filename="api-usage_2024-03-01_2024-03-15.csv"`)
+		assert.Equal(t, "date,api_key_id,endpoint,requests,errors,bytes_in,bytes_out\n", w.Body.String())
+	})
+
+	t.Run("range too long", func(t *testing.T) {
+		service := new(MockUsageService)
+		service.On("ExportCSV", mock.Anything, uint(7), mock.Anything, mock.Anything, mock.Anything).
+			Return(pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "查询范围不能超过366天"))
+
+		w := httptest.NewRecorder()
+		setupAPIUsageRouter(service).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/me/usage/export?from=2020-01-01", nil))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, utils.CodeValidationError, decodeShareResponse(t, w).Code)
+	})
+}
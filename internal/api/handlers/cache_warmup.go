@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/cache"
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/service/audit"
+)
+
+// CacheWarmupHandler 参考数据缓存预热处理器
+type CacheWarmupHandler struct {
+	warmer *cache.Warmer
+	bus    *cache.InvalidationBus
+	audit  audit.AdminAuditService
+	logger *zap.Logger
+}
+
+// NewCacheWarmupHandler 创建参考数据缓存预热处理器
+//
+// bus 可以为nil，此时只重新预热本实例的缓存
+func NewCacheWarmupHandler(warmer *cache.Warmer, bus *cache.InvalidationBus, logger *zap.Logger) *CacheWarmupHandler {
+	return &CacheWarmupHandler{
+		warmer: warmer,
+		bus:    bus,
+		logger: logger,
+	}
+}
+
+// SetAuditService 设置管理员操作审计服务，未设置时不记录审计日志
+func (h *CacheWarmupHandler) SetAuditService(service audit.AdminAuditService) {
+	h.audit = service
+}
+
+// WarmCacheRequest 缓存预热请求
+type WarmCacheRequest struct {
+	Sources []string `json:"sources"` // 需要重新加载的数据源，为空表示全部
+}
+
+// WarmCache 清除并重新加载参考数据缓存
+//
+// @Summary 重新预热参考数据缓存
+// @Description 清除套餐、策略、特性开关、保留用户名、MIME类型等参考数据的缓存并立即从数据源重新加载，用于管理员修改数据后使缓存生效。多实例部署时同时通知其他实例清除本地缓存
+// @Tags 系统
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body WarmCacheRequest false "数据源列表"
+// @Success 200 {object} utils.Response{data=cache.WarmReport} "预热完成(含各数据源结果)"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 403 {object} utils.Response "需要管理员权限"
+// @Router /api/v1/admin/cache/warmup [post]
+func (h *CacheWarmupHandler) WarmCache(c *gin.Context) {
+	var req WarmCacheRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+			return
+		}
+	}
+
+	registered := make(map[string]bool)
+	for _, name := range h.warmer.Sources() {
+		registered[name] = true
+	}
+	sources := req.Sources
+	if len(sources) == 0 {
+		sources = h.warmer.Sources()
+	}
+	for _, name := range sources {
+		if !registered[name] {
+			utils.ErrorWithMessage(c, utils.CodeValidationError, "未知的数据源: "+name)
+			return
+		}
+	}
+
+	// 先通知所有实例(包括本实例)清除本地副本，再由本实例重新加载并更新Redis共享副本
+	if h.bus != nil {
+		if err := h.bus.Publish(c.Request.Context(), cache.InvalidationReferenceData, sources...); err != nil {
+			h.logger.Warn("Failed to broadcast reference data invalidation",
+				zap.Strings("sources", sources),
+				zap.Error(err))
+		}
+	}
+
+	report := h.warmer.Invalidate(c.Request.Context(), sources...)
+	if report.Failed() > 0 {
+		h.logger.Warn("Reference data warmup finished with failures",
+			zap.Strings("sources", sources),
+			zap.Int("failed", report.Failed()),
+			zap.String("ip", c.ClientIP()))
+	} else {
+		h.logger.Info("Reference data cache rewarmed",
+			zap.Strings("sources", sources),
+			zap.Duration("duration", report.Duration),
+			zap.String("ip", c.ClientIP()))
+	}
+
+	entries := make([]*audit.Entry, 0, len(report.Results))
+	for _, result := range report.Results {
+		entries = append(entries, &audit.Entry{
+			Action:     audit.ActionCacheWarmup,
+			TargetType: audit.TargetCache,
+			TargetID:   result.Source,
+			After:      map[string]interface{}{"succeeded": result.Error == ""},
+		})
+	}
+	recordAdminAudit(c, h.audit, h.logger, entries...)
+
+	utils.Success(c, report)
+}
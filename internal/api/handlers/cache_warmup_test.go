@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/cache"
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/service/audit"
+)
+
+func setupCacheWarmupRouter(loads map[string]int) *gin.Engine {
+	warmer := cache.NewWarmer(nil, nil)
+	for _, name := range []string{"plans", "policies"} {
+		name := name
+		warmer.Register(cache.WarmSource{Name: name, Load: func(context.Context) (interface{}, error) {
+			loads[name]++
+			return name, nil
+		}})
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/admin/cache/warmup", NewCacheWarmupHandler(warmer, nil, zap.NewNop()).WarmCache)
+	return router
+}
+
+func TestWarmCache(t *testing.T) {
+	t.Run("all sources", func(t *testing.T) {
+		loads := make(map[string]int)
+		router := setupCacheWarmupRouter(loads)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/cache/warmup", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, map[string]int{"plans": 1, "policies": 1}, loads)
+	})
+
+	t.Run("selected sources", func(t *testing.T) {
+		loads := make(map[string]int)
+		router := setupCacheWarmupRouter(loads)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/cache/warmup", strings.NewReader(`{"sources":["plans"]}`)))
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, map[string]int{"plans": 1}, loads)
+	})
+
+	t.Run("unknown source", func(t *testing.T) {
+		router := setupCacheWarmupRouter(make(map[string]int))
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/cache/warmup", strings.NewReader(`{"sources":["nope"]}`)))
+
+		var resp utils.Response
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, utils.CodeValidationError, resp.Code)
+	})
+}
+
+func TestWarmCache_BroadcastsInvalidation(t *testing.T) {
+	warmer := cache.NewWarmer(nil, nil)
+	warmer.Register(cache.WarmSource{Name: "plans", Load: func(context.Context) (interface{}, error) {
+		return "plans", nil
+	}})
+
+	bus := cache.NewInvalidationBus(nil, nil)
+	var targets []string
+	bus.Subscribe(cache.InvalidationReferenceData, func(_ context.Context, msg *cache.Invalidation) {
+		targets = msg.Targets
+	})
+
+	auditService := &recordingAuditService{}
+	handler := NewCacheWarmupHandler(warmer, bus, zap.NewNop())
+	handler.SetAuditService(auditService)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/admin/cache/warmup", func(c *gin.Context) { c.Set("user_id", uint64(1)) }, handler.WarmCache)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/cache/warmup", strings.NewReader(`{"sources":["plans"]}`)))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"plans"}, targets)
+
+	// 每个数据源一条审计记录
+	require.Len(t, auditService.entries, 1)
+	assert.Equal(t, audit.ActionCacheWarmup, auditService.entries[0].Action)
+	assert.Equal(t, "plans", auditService.entries[0].TargetID)
+	assert.Equal(t, uint(1), auditService.entries[0].ActorID)
+}
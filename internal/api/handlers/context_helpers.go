@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"cloudpan/internal/pkg/captcha"
+	"cloudpan/internal/pkg/database"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/storage"
+	"cloudpan/internal/pkg/utils"
+	filesvc "cloudpan/internal/service/file"
+	sharesvc "cloudpan/internal/service/share"
+	"cloudpan/internal/service/user"
+)
+
+// getCurrentUserID 从上下文获取当前用户ID
+//
+// 认证中间件以uint64写入user_id，这里兼容uint和uint64两种类型
+func getCurrentUserID(c *gin.Context) (uint, bool) {
+	value, exists := c.Get("user_id")
+	if !exists {
+		return 0, false
+	}
+
+	switch id := value.(type) {
+	case uint64:
+		return uint(id), id > 0
+	case uint:
+		return id, id > 0
+	default:
+		return 0, false
+	}
+}
+
+// parseIDParam 解析路径中的数字ID参数
+func parseIDParam(c *gin.Context, name string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 64)
+	if err != nil || id == 0 {
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// withTransaction 在事务中执行fn，txManager为nil时直接执行(各步骤不保证原子性)
+func withTransaction(ctx context.Context, txManager database.TxManager, fn func(ctx context.Context) error) error {
+	if txManager == nil {
+		return fn(ctx)
+	}
+	return txManager.WithTransaction(ctx, fn)
+}
+
+// respondServiceError 将服务层错误转换为统一响应
+//
+// 文件名校验错误返回专用错误码，并在data.reason中给出机器可读的原因；
+// 分享流量用尽返回专用错误码，并在data中给出流量状态和恢复时间；
+// 存储空间不足在data中给出当前配额、已用和预留空间；
+// 人机验证未提交或不通过分别返回需要验证码和验证码错误；
+// 其他配额超出(存储空间、导出限制等)统一返回配额超出错误码；
+// 存储后端熔断或主存储和副本都不可用时返回服务不可用
+func respondServiceError(c *gin.Context, err error, fallbackMessage string) {
+	var nameErr *utils.FileNameError
+	var transferErr *sharesvc.TransferLimitError
+	var quotaErr *user.QuotaExceededError
+	var folderErr *filesvc.FolderLimitError
+	var clipboardErr *filesvc.ClipboardConflictError
+	var exportErr *filesvc.ExportLimitError
+	switch {
+	case errors.As(err, &nameErr):
+		utils.ErrorWithData(c, utils.CodeInvalidFileName, nameErr.Error(), gin.H{"reason": nameErr.Reason})
+	case errors.As(err, &transferErr):
+		utils.ErrorWithData(c, utils.CodeShareTransferLimit, transferErr.Error(), transferErr.Status)
+	case errors.As(err, &quotaErr):
+		utils.ErrorWithData(c, utils.CodeQuotaExceeded, quotaErr.Error(), quotaErr.QuotaDetails())
+	case errors.As(err, &folderErr):
+		code := utils.CodeFolderFull
+		if folderErr.Limit == filesvc.FolderLimitDepth {
+			code = utils.CodeFolderTooDeep
+		}
+		utils.ErrorWithData(c, code, folderErr.Error(), folderErr)
+	case errors.As(err, &clipboardErr):
+		utils.ErrorWithData(c, utils.CodeConflict, clipboardErr.Error(), clipboardErr)
+	case errors.As(err, &exportErr):
+		utils.ErrorWithData(c, utils.CodeDownloadTooLarge, exportErr.Error(), exportErr)
+	case errors.Is(err, captcha.ErrRequired):
+		utils.ErrorWithMessage(c, utils.CodeCaptchaRequired, "请先完成人机验证")
+	case errors.Is(err, captcha.ErrInvalid):
+		utils.ErrorWithMessage(c, utils.CodeCaptchaWrong, "人机验证未通过，请重试")
+	case errors.Is(err, pkgErrors.ErrQuotaExceeded):
+		utils.ErrorWithMessage(c, utils.CodeQuotaExceeded, err.Error())
+	case pkgErrors.IsNotFoundError(err):
+		utils.ErrorWithMessage(c, utils.CodeNotFound, err.Error())
+	case pkgErrors.IsPermissionError(err):
+		utils.ErrorWithMessage(c, utils.CodeForbidden, err.Error())
+	case pkgErrors.IsValidationError(err):
+		utils.ErrorWithMessage(c, utils.CodeValidationError, err.Error())
+	case errors.Is(err, pkgErrors.ErrResourceExists):
+		utils.ErrorWithMessage(c, utils.CodeConflict, err.Error())
+	case pkgErrors.IsRateLimitError(err):
+		utils.ErrorWithMessage(c, utils.CodeTooManyRequests, err.Error())
+	case errors.Is(err, storage.ErrBackendUnavailable):
+		utils.ErrorWithMessage(c, utils.CodeServiceUnavailable, "存储服务暂时不可用，请稍后重试")
+	default:
+		utils.InternalErrorWithMessage(c, fallbackMessage)
+	}
+}
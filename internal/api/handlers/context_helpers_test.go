@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/utils"
+	filesvc "cloudpan/internal/service/file"
+	sharesvc "cloudpan/internal/service/share"
+	"cloudpan/internal/service/user"
+)
+
+func TestRespondServiceError_FileName(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	_, nameErr := utils.NormalizeFileName("CON.txt")
+	require.Error(t, nameErr)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	respondServiceError(c, pkgErrors.WrapError(nameErr, "重命名失败"), "操作失败")
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var resp struct {
+		Code int               `json:"code"`
+		Data map[string]string `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, int(utils.CodeInvalidFileName), resp.Code)
+	assert.Equal(t, utils.FileNameReasonReservedName, resp.Data["reason"])
+}
+
+func TestRespondServiceError_ShareTransferLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	resetsAt := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	transferErr := &sharesvc.TransferLimitError{Status: &sharesvc.TransferStatus{Limit: 100, Used: 120, Exhausted: true, ResetsAt: resetsAt}}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	respondServiceError(c, transferErr, "下载失败")
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	var resp struct {
+		Code int                     `json:"code"`
+		Data sharesvc.TransferStatus `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, int(utils.CodeShareTransferLimit), resp.Code)
+	assert.True(t, resp.Data.ResetsAt.Equal(resetsAt))
+}
+
+func TestRespondServiceError_QuotaExceeded(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	respondServiceError(c, pkgErrors.WrapError(pkgErrors.ErrQuotaExceeded, "存储空间不足"), "上传失败")
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	var resp utils.Response
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, utils.CodeQuotaExceeded, resp.Code)
+	assert.Contains(t, resp.Message, "存储空间不足")
+}
+
+func TestRespondServiceError_StorageQuotaExceeded(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	quotaErr := &user.QuotaExceededError{
+		Usage:    &user.StorageUsage{Quota: 1000, Used: 800, Reserved: 150, Available: 50, UsagePercent: 95},
+		Required: 100,
+	}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	respondServiceError(c, quotaErr, "上传失败")
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	var resp struct {
+		Code int                       `json:"code"`
+		Data user.QuotaExceededDetails `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, int(utils.CodeQuotaExceeded), resp.Code)
+	assert.Equal(t, int64(50), resp.Data.Available)
+	assert.Equal(t, int64(150), resp.Data.Reserved)
+	assert.Equal(t, int64(100), resp.Data.Required)
+}
+
+func TestRespondServiceError_FolderLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		limit string
+		code  utils.ResponseCode
+	}{
+		{limit: filesvc.FolderLimitDepth, code: utils.CodeFolderTooDeep},
+		{limit: filesvc.FolderLimitChildren, code: utils.CodeFolderFull},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.limit, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			respondServiceError(c, &filesvc.FolderLimitError{Limit: tt.limit, Max: 10, Actual: 11}, "移动失败")
+
+			assert.Equal(t, http.StatusConflict, w.Code)
+			var resp struct {
+				Code int                      `json:"code"`
+				Data filesvc.FolderLimitError `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, int(tt.code), resp.Code)
+			assert.Equal(t, tt.limit, resp.Data.Limit)
+			assert.Equal(t, 10, resp.Data.Max)
+			assert.Equal(t, int64(11), resp.Data.Actual)
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/service/featureflag"
+)
+
+// FeatureFlagHandler 特性开关评估处理器
+type FeatureFlagHandler struct {
+	featureFlagService featureflag.FeatureFlagService
+	logger             *zap.Logger
+}
+
+// NewFeatureFlagHandler 创建特性开关评估处理器
+func NewFeatureFlagHandler(featureFlagService featureflag.FeatureFlagService, logger *zap.Logger) *FeatureFlagHandler {
+	return &FeatureFlagHandler{
+		featureFlagService: featureFlagService,
+		logger:             logger,
+	}
+}
+
+// EvaluateFeatures 评估当前用户的全部特性
+//
+// @Summary 评估全部特性开关
+// @Description 返回当前用户的全部特性评估结果，未登录时按匿名用户返回默认值。前端应使用该结果而不是自行计算灰度
+// @Tags 系统
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response{data=[]featureflag.Evaluation} "评估成功"
+// @Failure 404 {object} utils.Response "用户不存在"
+// @Failure 500 {object} utils.Response "内部服务器错误"
+// @Router /api/v1/features [get]
+func (h *FeatureFlagHandler) EvaluateFeatures(c *gin.Context) {
+	userID, _ := getCurrentUserID(c)
+
+	evaluations, err := h.featureFlagService.EvaluateAll(c.Request.Context(), userID)
+	if err != nil {
+		h.logger.Error("Failed to evaluate feature flags",
+			zap.Uint("user_id", userID),
+			zap.Error(err),
+			zap.String("ip", c.ClientIP()))
+		respondServiceError(c, err, "评估特性开关失败")
+		return
+	}
+
+	utils.Success(c, evaluations)
+}
+
+// EvaluateFeature 评估当前用户的单个特性
+//
+// @Summary 评估单个特性开关
+// @Description 返回当前用户对指定特性的评估结果和判定原因，特性不存在时返回未启用
+// @Tags 系统
+// @Produce json
+// @Security BearerAuth
+// @Param key path string true "特性键"
+// @Success 200 {object} utils.Response{data=featureflag.Evaluation} "评估成功"
+// @Failure 404 {object} utils.Response "用户不存在"
+// @Failure 500 {object} utils.Response "内部服务器错误"
+// @Router /api/v1/features/{key} [get]
+func (h *FeatureFlagHandler) EvaluateFeature(c *gin.Context) {
+	userID, _ := getCurrentUserID(c)
+	key := c.Param("key")
+
+	evaluation, err := h.featureFlagService.Evaluate(c.Request.Context(), key, userID)
+	if err != nil {
+		h.logger.Error("Failed to evaluate feature flag",
+			zap.String("key", key),
+			zap.Uint("user_id", userID),
+			zap.Error(err),
+			zap.String("ip", c.ClientIP()))
+		respondServiceError(c, err, "评估特性开关失败")
+		return
+	}
+
+	utils.Success(c, evaluation)
+}
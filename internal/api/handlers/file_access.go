@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+
+	"cloudpan/internal/pkg/utils"
+)
+
+// FileAuthorizer 文件授权检查，由 acl.PermissionService 实现
+//
+// 返回调用文件服务时使用的用户ID：获得授权的用户换成文件所有者，其他情况返回用户本身
+type FileAuthorizer interface {
+	ResolveActingUser(ctx context.Context, userID, fileID uint, permission string) (uint, error)
+}
+
+// fileAccess 文件处理器共用的授权检查，未设置授权服务时只有文件所有者可以访问
+type fileAccess struct {
+	authorizer FileAuthorizer
+}
+
+// SetFileAuthorizer 设置文件授权服务，使获得授权的用户可以访问他人的文件
+func (a *fileAccess) SetFileAuthorizer(authorizer FileAuthorizer) {
+	a.authorizer = authorizer
+}
+
+// actingUser 返回以指定权限访问文件时使用的用户ID，失败时已写入响应
+func (a *fileAccess) actingUser(c *gin.Context, userID, fileID uint, permission string) (uint, bool) {
+	actingUserID, err := a.resolveActingUser(c.Request.Context(), userID, fileID, permission)
+	if err != nil {
+		respondServiceError(c, err, "检查文件权限失败")
+		return 0, false
+	}
+	return actingUserID, true
+}
+
+// resolveActingUser 返回以指定权限访问文件时使用的用户ID，不写入响应
+func (a *fileAccess) resolveActingUser(ctx context.Context, userID, fileID uint, permission string) (uint, error) {
+	if a.authorizer == nil {
+		return userID, nil
+	}
+	return a.authorizer.ResolveActingUser(ctx, userID, fileID, permission)
+}
+
+// checkTarget 检查能否把文件放入目标文件夹，失败时已写入响应
+//
+// 文件和目标文件夹必须属于同一所有者；获得授权的用户不能把他人的文件放到根目录
+func (a *fileAccess) checkTarget(c *gin.Context, userID, actingUserID uint, parentID *uint, permission string) bool {
+	targetUserID := userID
+	if parentID != nil {
+		var ok bool
+		if targetUserID, ok = a.actingUser(c, userID, *parentID, permission); !ok {
+			return false
+		}
+	}
+	if message := targetDenial(actingUserID, targetUserID, parentID); message != "" {
+		utils.ErrorWithMessage(c, utils.CodeForbidden, message)
+		return false
+	}
+	return true
+}
+
+// targetDenial 返回不能把文件放入目标文件夹的原因，可以放入时返回空
+//
+// targetUserID 为访问目标文件夹时使用的用户ID，目标为根目录时是当前用户
+func targetDenial(actingUserID, targetUserID uint, parentID *uint) string {
+	switch {
+	case targetUserID == actingUserID:
+		return ""
+	case parentID == nil:
+		return "只能放入有写入权限的文件夹"
+	default:
+		return "不能在不同所有者的文件之间移动或复制"
+	}
+}
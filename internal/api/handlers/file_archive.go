@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+	"cloudpan/internal/service/file"
+)
+
+// FileArchiveHandler 归档文件恢复处理器
+type FileArchiveHandler struct {
+	fileAccess
+	service file.ArchiveService
+	logger  *zap.Logger
+}
+
+// NewFileArchiveHandler 创建归档文件恢复处理器
+func NewFileArchiveHandler(service file.ArchiveService, logger *zap.Logger) *FileArchiveHandler {
+	return &FileArchiveHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// RestoreFileRequest 归档文件恢复请求
+type RestoreFileRequest struct {
+	Days int `json:"days"` // 恢复副本保留天数，为0时使用默认值
+}
+
+// GetArchiveStatus 查询文件归档状态
+//
+// @Summary 查询文件归档状态
+// @Description 返回文件的归档状态：available(未归档)、archived(已归档，需先恢复)、restoring(恢复中，按poll_interval秒轮询)、restored(已恢复，restored_until之前可下载)
+// @Tags 文件
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "文件ID"
+// @Success 200 {object} utils.Response{data=file.ArchiveStatus} "归档状态"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "无权访问该文件"
+// @Failure 404 {object} utils.Response "文件不存在"
+// @Router /api/v1/files/{id}/archive [get]
+func (h *FileArchiveHandler) GetArchiveStatus(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+	fileID, ok := parseIDParam(c, "id")
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "文件ID格式错误")
+		return
+	}
+
+	actingUserID, ok := h.actingUser(c, userID, fileID, models.FilePermissionRead)
+	if !ok {
+		return
+	}
+
+	status, err := h.service.GetArchiveStatus(c.Request.Context(), actingUserID, fileID)
+	if err != nil {
+		respondServiceError(c, err, "查询归档状态失败")
+		return
+	}
+
+	utils.Success(c, status)
+}
+
+// RequestRestore 发起归档文件恢复
+//
+// @Summary 恢复归档文件
+// @Description 对已归档的文件发起恢复，恢复通常需要数小时，期间通过归档状态接口轮询。恢复中重复发起直接返回当前状态，已恢复的文件再次发起会延长保留期
+// @Tags 文件
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "文件ID"
+// @Param request body RestoreFileRequest false "恢复选项"
+// @Success 200 {object} utils.Response{data=file.ArchiveStatus} "恢复已发起"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "无权访问或文件未归档"
+// @Failure 404 {object} utils.Response "文件不存在"
+// @Router /api/v1/files/{id}/restore [post]
+func (h *FileArchiveHandler) RequestRestore(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+	fileID, ok := parseIDParam(c, "id")
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "文件ID格式错误")
+		return
+	}
+
+	// 请求体可省略，省略时使用默认保留天数
+	var req RestoreFileRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+			return
+		}
+	}
+
+	actingUserID, ok := h.actingUser(c, userID, fileID, models.FilePermissionWrite)
+	if !ok {
+		return
+	}
+
+	status, err := h.service.RequestRestore(c.Request.Context(), actingUserID, fileID, req.Days)
+	if err != nil {
+		respondServiceError(c, err, "发起文件恢复失败")
+		return
+	}
+
+	h.logger.Info("File restore requested",
+		zap.Uint("user_id", userID),
+		zap.Uint("file_id", fileID),
+		zap.String("state", status.State),
+		zap.String("ip", c.ClientIP()))
+	utils.Success(c, status)
+}
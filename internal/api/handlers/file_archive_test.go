@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+	"cloudpan/internal/service/file"
+)
+
+// MockArchiveService 模拟归档存储服务
+type MockArchiveService struct {
+	mock.Mock
+}
+
+func (m *MockArchiveService) TransitionColdFiles(ctx context.Context) (*file.TransitionReport, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*file.TransitionReport), args.Error(1)
+}
+
+func (m *MockArchiveService) RequestRestore(ctx context.Context, userID, fileID uint, days int) (*file.ArchiveStatus, error) {
+	args := m.Called(ctx, userID, fileID, days)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*file.ArchiveStatus), args.Error(1)
+}
+
+func (m *MockArchiveService) GetArchiveStatus(ctx context.Context, userID, fileID uint) (*file.ArchiveStatus, error) {
+	args := m.Called(ctx, userID, fileID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*file.ArchiveStatus), args.Error(1)
+}
+
+func setupFileArchiveRouter(service *MockArchiveService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler := NewFileArchiveHandler(service, zap.NewNop())
+	authed := router.Group("", func(c *gin.Context) {
+		c.Set("user_id", uint64(7))
+	})
+	authed.GET("/files/:id/archive", handler.GetArchiveStatus)
+	authed.POST("/files/:id/restore", handler.RequestRestore)
+	return router
+}
+
+func TestFileArchiveHandler_GetArchiveStatus(t *testing.T) {
+	service := new(MockArchiveService)
+	service.On("GetArchiveStatus", mock.Anything, uint(7), uint(3)).
+		Return(&file.ArchiveStatus{FileID: 3, State: models.ArchiveStateRestoring, PollInterval: 300}, nil)
+
+	w := httptest.NewRecorder()
+	setupFileArchiveRouter(service).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/files/3/archive", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Data file.ArchiveStatus `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, models.ArchiveStateRestoring, resp.Data.State)
+	assert.Equal(t, 300, resp.Data.PollInterval)
+}
+
+func TestFileArchiveHandler_RequestRestore(t *testing.T) {
+	t.Run("default days without body", func(t *testing.T) {
+		service := new(MockArchiveService)
+		service.On("RequestRestore", mock.Anything, uint(7), uint(3), 0).
+			Return(&file.ArchiveStatus{FileID: 3, State: models.ArchiveStateRestoring}, nil)
+
+		w := httptest.NewRecorder()
+		setupFileArchiveRouter(service).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/files/3/restore", nil))
+
+		require.Equal(t, http.StatusOK, w.Code)
+		service.AssertExpectations(t)
+	})
+
+	t.Run("custom days", func(t *testing.T) {
+		service := new(MockArchiveService)
+		service.On("RequestRestore", mock.Anything, uint(7), uint(3), 14).
+			Return(&file.ArchiveStatus{FileID: 3, State: models.ArchiveStateRestoring}, nil)
+
+		w := httptest.NewRecorder()
+		setupFileArchiveRouter(service).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/files/3/restore", strings.NewReader(`{"days":14}`)))
+
+		require.Equal(t, http.StatusOK, w.Code)
+		service.AssertExpectations(t)
+	})
+
+	t.Run("not archived", func(t *testing.T) {
+		service := new(MockArchiveService)
+		service.On("RequestRestore", mock.Anything, uint(7), uint(3), 0).
+			Return(nil, pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "文件未归档，无需恢复"))
+
+		w := httptest.NewRecorder()
+		setupFileArchiveRouter(service).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/files/3/restore", nil))
+
+		assert.Equal(t, utils.CodeForbidden, decodeShareResponse(t, w).Code)
+	})
+}
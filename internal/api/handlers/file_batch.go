@@ -0,0 +1,319 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/storage"
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+	"cloudpan/internal/service/audit"
+	filesvc "cloudpan/internal/service/file"
+	"cloudpan/internal/service/user"
+)
+
+// MaxBatchFileItems 单次批量操作最多处理的项目数
+const MaxBatchFileItems = 100
+
+// 批量操作类型
+const (
+	BatchOperationMove    = "move"    // 移动到目标文件夹
+	BatchOperationCopy    = "copy"    // 复制到目标文件夹
+	BatchOperationDelete  = "delete"  // 移入回收站
+	BatchOperationRestore = "restore" // 从回收站恢复
+	BatchOperationTag     = "tag"     // 添加标签
+	BatchOperationUntag   = "untag"   // 删除标签
+)
+
+// 批量操作单项失败原因
+const (
+	BatchReasonNotFound      = "not_found"      // 文件或回收站项目不存在
+	BatchReasonForbidden     = "forbidden"      // 无权操作或当前不可操作
+	BatchReasonInvalid       = "invalid"        // 参数或文件名不合法
+	BatchReasonConflict      = "conflict"       // 与现有数据冲突
+	BatchReasonQuotaExceeded = "quota_exceeded" // 存储空间不足
+	BatchReasonFolderLimit   = "folder_limit"   // 超出文件夹项目数或层级限制
+	BatchReasonUnavailable   = "unavailable"    // 存储服务暂时不可用
+	BatchReasonFailed        = "failed"         // 其他错误
+)
+
+// FileBatchHandler 文件批量操作处理器
+//
+// 逐项调用移动、复制、删除、恢复和标签服务，每一项在各自的事务中完成，
+// 某一项失败不影响其他项目；未设置的服务对应的操作不可用
+type FileBatchHandler struct {
+	fileAccess
+	securityAudit
+	tree   filesvc.TreeService
+	trash  filesvc.TrashService
+	tags   filesvc.TagService
+	logger *zap.Logger
+}
+
+// NewFileBatchHandler 创建文件批量操作处理器，服务为nil时对应的操作不可用
+func NewFileBatchHandler(tree filesvc.TreeService, trash filesvc.TrashService, tags filesvc.TagService, logger *zap.Logger) *FileBatchHandler {
+	return &FileBatchHandler{
+		tree:   tree,
+		trash:  trash,
+		tags:   tags,
+		logger: logger,
+	}
+}
+
+// BatchFileRequest 批量操作请求
+type BatchFileRequest struct {
+	Operation string `json:"operation" binding:"required"` // 操作类型(move/copy/delete/restore/tag/untag)
+	IDs       []uint `json:"ids" binding:"required"`       // 文件ID，restore操作为回收站项目ID，最多100个
+	ParentID  *uint  `json:"parent_id"`                    // move/copy的目标文件夹ID，为空表示根目录
+	Tag       string `json:"tag"`                          // tag/untag的标签名称
+	Color     string `json:"color"`                        // tag的标签颜色，如#1890ff
+}
+
+// BatchItemResult 批量操作中单个项目的结果
+type BatchItemResult struct {
+	ID      uint        `json:"id"`                // 请求中的文件ID或回收站项目ID
+	Success bool        `json:"success"`           // 是否成功
+	Reason  string      `json:"reason,omitempty"`  // 失败原因(not_found/forbidden/invalid/conflict/quota_exceeded/folder_limit/unavailable/failed)
+	Message string      `json:"message,omitempty"` // 失败说明
+	Data    interface{} `json:"data,omitempty"`    // 成功时的结果，与单项接口的返回相同
+}
+
+// BatchFileResult 批量操作结果
+type BatchFileResult struct {
+	Operation string             `json:"operation"` // 操作类型
+	Total     int                `json:"total"`     // 处理的项目数(去重后)
+	Succeeded int                `json:"succeeded"` // 成功数
+	Failed    int                `json:"failed"`    // 失败数
+	Items     []*BatchItemResult `json:"items"`     // 各项目结果，顺序与请求一致
+}
+
+// batchItemFunc 处理单个项目，返回成功时的结果
+type batchItemFunc func(ctx context.Context, id uint) (interface{}, error)
+
+// BatchFiles 批量操作文件
+//
+// @Summary 批量操作文件
+// @Description 对多个文件执行同一操作：move/copy到parent_id，delete移入回收站，restore恢复回收站项目(ids为回收站项目ID)，tag/untag添加或删除标签。每一项单独完成，某一项失败不影响其他项目，逐项返回结果和失败原因。重复的ID只处理一次，单次最多100个。获得授权的用户需要具备与单项接口相同的权限
+// @Tags 文件
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body BatchFileRequest true "操作类型、ID和操作参数"
+// @Success 200 {object} utils.Response{data=BatchFileResult} "各项目的结果"
+// @Failure 400 {object} utils.Response "请求参数错误、操作类型不支持或ID数量超出限制"
+// @Failure 401 {object} utils.Response "未认证"
+// @Router /api/v1/files/batch [post]
+func (h *FileBatchHandler) BatchFiles(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	var req BatchFileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+		return
+	}
+	ids := uniqueIDs(req.IDs)
+	if len(ids) == 0 {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请选择要操作的文件")
+		return
+	}
+	if len(ids) > MaxBatchFileItems {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "单次最多操作"+strconv.Itoa(MaxBatchFileItems)+"个文件")
+		return
+	}
+	if (req.Operation == BatchOperationTag || req.Operation == BatchOperationUntag) && req.Tag == "" {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请指定标签名称")
+		return
+	}
+
+	process, fallback := h.operation(c, userID, &req)
+	if process == nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "不支持的批量操作: "+req.Operation)
+		return
+	}
+
+	ctx := c.Request.Context()
+	result := &BatchFileResult{Operation: req.Operation, Total: len(ids), Items: make([]*BatchItemResult, 0, len(ids))}
+	for _, id := range ids {
+		item := &BatchItemResult{ID: id}
+		data, err := process(ctx, id)
+		if err == nil {
+			item.Success, item.Data = true, data
+			result.Succeeded++
+		} else {
+			item.Reason, item.Message = batchFailure(err, fallback)
+			if item.Reason == BatchReasonFailed {
+				h.logger.Error("Batch file operation failed",
+					zap.String("operation", req.Operation),
+					zap.Uint("user_id", userID),
+					zap.Uint("id", id),
+					zap.Error(err))
+			}
+			result.Failed++
+		}
+		result.Items = append(result.Items, item)
+	}
+
+	h.logger.Info("Batch file operation completed",
+		zap.String("operation", req.Operation),
+		zap.Uint("user_id", userID),
+		zap.Int("total", result.Total),
+		zap.Int("succeeded", result.Succeeded),
+		zap.Int("failed", result.Failed))
+	utils.Success(c, result)
+}
+
+// operation 返回处理单个项目的函数和失败时的默认说明，操作不支持时返回nil
+func (h *FileBatchHandler) operation(c *gin.Context, userID uint, req *BatchFileRequest) (batchItemFunc, string) {
+	switch req.Operation {
+	case BatchOperationMove:
+		if h.tree == nil {
+			return nil, ""
+		}
+		return h.transfer(c.Request.Context(), userID, req.ParentID, models.FilePermissionWrite, h.tree.Move), "移动文件失败"
+	case BatchOperationCopy:
+		if h.tree == nil {
+			return nil, ""
+		}
+		return h.transfer(c.Request.Context(), userID, req.ParentID, models.FilePermissionRead, h.tree.Copy), "复制文件失败"
+	case BatchOperationDelete:
+		if h.trash == nil {
+			return nil, ""
+		}
+		return func(_ context.Context, fileID uint) (interface{}, error) {
+			return h.moveToTrash(c, userID, fileID)
+		}, "删除文件失败"
+	case BatchOperationRestore:
+		if h.trash == nil {
+			return nil, ""
+		}
+		// 回收站项目只属于当前用户
+		return func(ctx context.Context, itemID uint) (interface{}, error) {
+			return h.trash.Restore(ctx, userID, itemID)
+		}, "恢复文件失败"
+	case BatchOperationTag:
+		if h.tags == nil {
+			return nil, ""
+		}
+		tagReq := &filesvc.AddTagRequest{Tag: req.Tag, Color: req.Color}
+		return func(ctx context.Context, fileID uint) (interface{}, error) {
+			actingUserID, err := h.resolveActingUser(ctx, userID, fileID, models.FilePermissionWrite)
+			if err != nil {
+				return nil, err
+			}
+			return h.tags.AddTag(ctx, actingUserID, fileID, tagReq)
+		}, "添加文件标签失败"
+	case BatchOperationUntag:
+		if h.tags == nil {
+			return nil, ""
+		}
+		return func(ctx context.Context, fileID uint) (interface{}, error) {
+			actingUserID, err := h.resolveActingUser(ctx, userID, fileID, models.FilePermissionWrite)
+			if err != nil {
+				return nil, err
+			}
+			return nil, h.tags.RemoveTag(ctx, actingUserID, fileID, req.Tag)
+		}, "删除文件标签失败"
+	default:
+		return nil, ""
+	}
+}
+
+// transfer 返回移动或复制单个文件的函数
+//
+// 目标文件夹的授权只检查一次；文件和目标文件夹必须属于同一所有者
+func (h *FileBatchHandler) transfer(ctx context.Context, userID uint, parentID *uint, permission string,
+	fn func(ctx context.Context, userID, fileID uint, targetParentID *uint) (*models.File, error)) batchItemFunc {
+	targetUserID := userID
+	var targetErr error
+	if parentID != nil {
+		targetUserID, targetErr = h.resolveActingUser(ctx, userID, *parentID, models.FilePermissionWrite)
+	}
+
+	return func(ctx context.Context, fileID uint) (interface{}, error) {
+		if targetErr != nil {
+			return nil, targetErr
+		}
+		actingUserID, err := h.resolveActingUser(ctx, userID, fileID, permission)
+		if err != nil {
+			return nil, err
+		}
+		if message := targetDenial(actingUserID, targetUserID, parentID); message != "" {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, message)
+		}
+		return fn(ctx, actingUserID, fileID, parentID)
+	}
+}
+
+// moveToTrash 将单个文件移入回收站并写入安全审计
+func (h *FileBatchHandler) moveToTrash(c *gin.Context, userID, fileID uint) (*filesvc.TrashItem, error) {
+	ctx := c.Request.Context()
+	actingUserID, err := h.resolveActingUser(ctx, userID, fileID, models.FilePermissionDelete)
+	if err != nil {
+		return nil, err
+	}
+	item, err := h.trash.MoveToTrash(ctx, actingUserID, fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	h.recordSecurity(c, h.logger, &audit.Event{
+		UserID:       userID,
+		Action:       audit.SecurityActionFileTrash,
+		ResourceType: audit.ResourceFile,
+		ResourceID:   strconv.FormatUint(uint64(fileID), 10),
+		ResourceName: item.Name,
+		Before:       map[string]interface{}{"path": item.OriginalPath, "is_folder": item.IsFolder, "size": item.Size, "owner_id": actingUserID},
+		After:        map[string]interface{}{"trash_id": item.ID, "auto_delete_at": item.AutoDeleteAt, "batch": true},
+	})
+	return item, nil
+}
+
+// batchFailure 返回单个项目失败的原因和说明，与 respondServiceError 的错误分类一致
+func batchFailure(err error, fallbackMessage string) (string, string) {
+	var nameErr *utils.FileNameError
+	var quotaErr *user.QuotaExceededError
+	var folderErr *filesvc.FolderLimitError
+	switch {
+	case errors.As(err, &nameErr):
+		return BatchReasonInvalid, nameErr.Error()
+	case errors.As(err, &quotaErr), errors.Is(err, pkgErrors.ErrQuotaExceeded):
+		return BatchReasonQuotaExceeded, err.Error()
+	case errors.As(err, &folderErr):
+		return BatchReasonFolderLimit, folderErr.Error()
+	case pkgErrors.IsNotFoundError(err):
+		return BatchReasonNotFound, err.Error()
+	case pkgErrors.IsPermissionError(err):
+		return BatchReasonForbidden, err.Error()
+	case pkgErrors.IsValidationError(err):
+		return BatchReasonInvalid, err.Error()
+	case errors.Is(err, pkgErrors.ErrResourceExists):
+		return BatchReasonConflict, err.Error()
+	case errors.Is(err, storage.ErrBackendUnavailable):
+		return BatchReasonUnavailable, "存储服务暂时不可用，请稍后重试"
+	default:
+		return BatchReasonFailed, fallbackMessage
+	}
+}
+
+// uniqueIDs 去除重复和为0的ID，保持原有顺序
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	result := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if id == 0 || seen[id] {
+			continue
+		}
+		seen[id] = true
+		result = append(result, id)
+	}
+	return result
+}
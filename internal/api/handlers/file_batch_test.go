@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/storage"
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+	"cloudpan/internal/service/file"
+)
+
+func setupFileBatchRouter(handler *FileBatchHandler) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	authed := router.Group("", func(c *gin.Context) {
+		c.Set("user_id", uint64(7))
+	})
+	authed.POST("/files/batch", handler.BatchFiles)
+	return router
+}
+
+func postBatch(t *testing.T, router *gin.Engine, body string) (*httptest.ResponseRecorder, *BatchFileResult) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/files/batch", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		return w, nil
+	}
+
+	var resp struct {
+		Data BatchFileResult `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return w, &resp.Data
+}
+
+func TestFileBatchHandler_PerItemResults(t *testing.T) {
+	tree := new(MockTreeService)
+	parentID := uint(9)
+	tree.On("Move", mock.Anything, uint(7), uint(1), &parentID).Return(&models.File{Name: "a.txt"}, nil)
+	tree.On("Move", mock.Anything, uint(7), uint(2), &parentID).
+		Return(nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "文件不存在"))
+	tree.On("Move", mock.Anything, uint(7), uint(3), &parentID).
+		Return(nil, pkgErrors.WrapError(pkgErrors.ErrResourceExists, "目标文件夹下已存在同名文件"))
+	tree.On("Move", mock.Anything, uint(7), uint(4), &parentID).
+		Return(nil, storage.ErrBackendUnavailable)
+	router := setupFileBatchRouter(NewFileBatchHandler(tree, nil, nil, zap.NewNop()))
+
+	// 重复的ID只处理一次，结果顺序与请求一致
+	w, result := postBatch(t, router, `{"operation":"move","ids":[1,2,1,3,4],"parent_id":9}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 4, result.Total)
+	assert.Equal(t, 1, result.Succeeded)
+	assert.Equal(t, 3, result.Failed)
+	require.Len(t, result.Items, 4)
+	assert.True(t, result.Items[0].Success)
+	assert.NotNil(t, result.Items[0].Data)
+	assert.Equal(t, BatchReasonNotFound, result.Items[1].Reason)
+	assert.Equal(t, uint(3), result.Items[2].ID)
+	assert.Equal(t, BatchReasonConflict, result.Items[2].Reason)
+	assert.Equal(t, BatchReasonUnavailable, result.Items[3].Reason)
+	tree.AssertNumberOfCalls(t, "Move", 4)
+}
+
+func TestFileBatchHandler_DeleteRestoreAndTag(t *testing.T) {
+	trash := new(MockTrashService)
+	trash.On("MoveToTrash", mock.Anything, uint(7), uint(1)).Return(&file.TrashItem{ID: 11, Name: "a.txt"}, nil)
+	trash.On("Restore", mock.Anything, uint(7), uint(11)).Return(&file.RestoredFile{FileID: 1, Name: "a.txt"}, nil)
+	trash.On("Restore", mock.Anything, uint(7), uint(12)).
+		Return(nil, pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "回收站项目已过保留期，无法恢复"))
+	tags := new(MockTagService)
+	tags.On("AddTag", mock.Anything, uint(7), uint(1), &file.AddTagRequest{Tag: "work", Color: "#1890ff"}).
+		Return(&file.FileTag{Tag: "work"}, nil)
+	tags.On("RemoveTag", mock.Anything, uint(7), uint(1), "work").Return(nil)
+	router := setupFileBatchRouter(NewFileBatchHandler(nil, trash, tags, zap.NewNop()))
+
+	_, result := postBatch(t, router, `{"operation":"delete","ids":[1]}`)
+	assert.Equal(t, 1, result.Succeeded)
+
+	_, result = postBatch(t, router, `{"operation":"restore","ids":[11,12]}`)
+	assert.Equal(t, 1, result.Succeeded)
+	assert.Equal(t, BatchReasonForbidden, result.Items[1].Reason)
+	assert.Contains(t, result.Items[1].Message, "保留期")
+
+	_, result = postBatch(t, router, `{"operation":"tag","ids":[1],"tag":"work","color":"#1890ff"}`)
+	assert.Equal(t, 1, result.Succeeded)
+	_, result = postBatch(t, router, `{"operation":"untag","ids":[1],"tag":"work"}`)
+	assert.Equal(t, 1, result.Succeeded)
+
+	// 未设置文件树服务时不支持移动
+	w, _ := postBatch(t, router, `{"operation":"move","ids":[1]}`)
+	assert.Equal(t, utils.CodeBadRequest, decodeShareResponse(t, w).Code)
+	trash.AssertExpectations(t)
+	tags.AssertExpectations(t)
+}
+
+func TestFileBatchHandler_InvalidRequests(t *testing.T) {
+	router := setupFileBatchRouter(NewFileBatchHandler(new(MockTreeService), nil, new(MockTagService), zap.NewNop()))
+
+	ids := make([]string, MaxBatchFileItems+1)
+	for i := range ids {
+		ids[i] = strconv.Itoa(i + 1)
+	}
+	bodies := []string{
+		`{"operation":"move"}`,
+		`{"operation":"move","ids":[]}`,
+		`{"operation":"rename","ids":[1]}`,
+		`{"operation":"tag","ids":[1]}`,
+		`{"operation":"move","ids":[` + strings.Join(ids, ",") + `]}`,
+	}
+	for _, body := range bodies {
+		w, _ := postBatch(t, router, body)
+		assert.Equal(t, utils.CodeBadRequest, decodeShareResponse(t, w).Code, body)
+	}
+}
+
+func TestFileBatchHandler_Grants(t *testing.T) {
+	// 用户7对用户1的文件3和文件夹5有write权限，对文件4只有read权限
+	authorizer := &stubFileAuthorizer{owners: map[uint]map[string]uint{
+		3: {models.FilePermissionRead: 1, models.FilePermissionWrite: 1},
+		4: {models.FilePermissionRead: 1},
+		5: {models.FilePermissionRead: 1, models.FilePermissionWrite: 1},
+	}}
+	tree := new(MockTreeService)
+	parentID := uint(5)
+	tree.On("Move", mock.Anything, uint(1), uint(3), &parentID).Return(&models.File{Name: "a.txt"}, nil)
+	handler := NewFileBatchHandler(tree, nil, nil, zap.NewNop())
+	handler.SetFileAuthorizer(authorizer)
+	router := setupFileBatchRouter(handler)
+
+	// 文件3属于用户1，文件8属于用户7，不能一起移入用户1的文件夹
+	_, result := postBatch(t, router, `{"operation":"move","ids":[3,8],"parent_id":5}`)
+	assert.True(t, result.Items[0].Success)
+	assert.Equal(t, BatchReasonForbidden, result.Items[1].Reason)
+	assert.Contains(t, result.Items[1].Message, "不能在不同所有者的文件之间移动或复制")
+
+	// 获得授权的用户不能把他人的文件复制到根目录
+	_, result = postBatch(t, router, `{"operation":"copy","ids":[4]}`)
+	assert.Equal(t, BatchReasonForbidden, result.Items[0].Reason)
+	assert.Contains(t, result.Items[0].Message, "只能放入有写入权限的文件夹")
+	tree.AssertNotCalled(t, "Copy", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	tree.AssertExpectations(t)
+}
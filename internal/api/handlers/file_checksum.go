@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+	"cloudpan/internal/service/file"
+)
+
+// FileChecksumHandler 文件夹完整性校验处理器
+type FileChecksumHandler struct {
+	fileAccess
+	fileService file.FileService
+	logger      *zap.Logger
+}
+
+// NewFileChecksumHandler 创建文件夹完整性校验处理器
+func NewFileChecksumHandler(fileService file.FileService, logger *zap.Logger) *FileChecksumHandler {
+	return &FileChecksumHandler{
+		fileService: fileService,
+		logger:      logger,
+	}
+}
+
+// GetFolderChecksum 获取文件夹组合校验和
+//
+// @Summary 获取文件夹组合校验和
+// @Description 返回文件夹子树的确定性组合校验和（子项按名称排序后哈希），供同步客户端和备份校验完整性
+// @Tags 文件
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "文件夹ID"
+// @Success 200 {object} utils.Response{data=file.FolderChecksum} "获取成功"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "无权访问"
+// @Failure 404 {object} utils.Response "文件夹不存在"
+// @Failure 500 {object} utils.Response "内部服务器错误"
+// @Router /api/v1/files/{id}/checksum [get]
+func (h *FileChecksumHandler) GetFolderChecksum(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	folderID, ok := parseIDParam(c, "id")
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "文件夹ID格式错误")
+		return
+	}
+
+	actingUserID, ok := h.actingUser(c, userID, folderID, models.FilePermissionRead)
+	if !ok {
+		return
+	}
+
+	checksum, err := h.fileService.GetFolderChecksum(ctx, actingUserID, folderID)
+	if err != nil {
+		h.logger.Warn("Failed to get folder checksum",
+			zap.Uint("user_id", userID),
+			zap.Uint("folder_id", folderID),
+			zap.Error(err),
+			zap.String("ip", c.ClientIP()))
+		respondServiceError(c, err, "文件夹校验和计算失败")
+		return
+	}
+
+	utils.Success(c, checksum)
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/utils"
+	filesvc "cloudpan/internal/service/file"
+)
+
+// FileClipboardHandler 服务端剪贴板处理器
+type FileClipboardHandler struct {
+	service filesvc.ClipboardService
+	logger  *zap.Logger
+}
+
+// NewFileClipboardHandler 创建服务端剪贴板处理器
+func NewFileClipboardHandler(service filesvc.ClipboardService, logger *zap.Logger) *FileClipboardHandler {
+	return &FileClipboardHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// SetClipboardRequest 写入剪贴板请求
+type SetClipboardRequest struct {
+	Operation string `json:"operation" binding:"required"` // 操作类型(copy/move)
+	FileIDs   []uint `json:"file_ids" binding:"required"`  // 文件ID，最多500个
+}
+
+// PasteClipboardRequest 粘贴请求
+type PasteClipboardRequest struct {
+	ParentID *uint `json:"parent_id"` // 目标文件夹ID，为空表示根目录
+}
+
+// GetClipboard 查看剪贴板
+//
+// @Summary 查看剪贴板
+// @Description 返回当前用户剪贴板中仍然可用的文件，已删除或不可用的文件ID列在missing中。剪贴板保存在服务端，可在其他设备或会话中查看和粘贴，超过24小时未写入时过期
+// @Tags 文件
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response{data=filesvc.ClipboardView} "剪贴板内容，为空时items为空"
+// @Failure 401 {object} utils.Response "未认证"
+// @Router /api/v1/files/clipboard [get]
+func (h *FileClipboardHandler) GetClipboard(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	clipboard, err := h.service.Get(c.Request.Context(), userID)
+	if err != nil {
+		respondServiceError(c, err, "查看剪贴板失败")
+		return
+	}
+
+	utils.Success(c, clipboard)
+}
+
+// SetClipboard 剪切或复制文件到剪贴板
+//
+// @Summary 写入剪贴板
+// @Description 将文件以剪切(move)或复制(copy)方式放入剪贴板，整体覆盖之前的内容。文件必须属于当前用户且可用
+// @Tags 文件
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body SetClipboardRequest true "操作类型和文件ID"
+// @Success 200 {object} utils.Response{data=filesvc.ClipboardView} "剪贴板内容"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 404 {object} utils.Response "文件不存在或不可用"
+// @Router /api/v1/files/clipboard [put]
+func (h *FileClipboardHandler) SetClipboard(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	var req SetClipboardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+		return
+	}
+
+	clipboard, err := h.service.Set(c.Request.Context(), userID, req.Operation, req.FileIDs)
+	if err != nil {
+		respondServiceError(c, err, "写入剪贴板失败")
+		return
+	}
+
+	utils.Success(c, clipboard)
+}
+
+// ClearClipboard 清空剪贴板
+//
+// @Summary 清空剪贴板
+// @Description 清空当前用户的剪贴板，文件不受影响
+// @Tags 文件
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response "清空成功"
+// @Failure 401 {object} utils.Response "未认证"
+// @Router /api/v1/files/clipboard [delete]
+func (h *FileClipboardHandler) ClearClipboard(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	if err := h.service.Clear(c.Request.Context(), userID); err != nil {
+		respondServiceError(c, err, "清空剪贴板失败")
+		return
+	}
+
+	utils.Success(c, nil)
+}
+
+// PasteClipboard 粘贴剪贴板
+//
+// @Summary 粘贴剪贴板
+// @Description 将剪贴板中的文件移动或复制到目标文件夹。粘贴前检查全部项目：文件已删除或不可用、剪切时目标位置有同名文件、文件夹粘贴到自身或子文件夹中时不做任何修改，返回409和冲突列表。复制时重名自动追加序号且剪贴板保留；剪切全部成功后清空剪贴板，部分失败时只保留失败的项目
+// @Tags 文件
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body PasteClipboardRequest true "目标文件夹"
+// @Success 200 {object} utils.Response{data=filesvc.PasteResult} "粘贴结果"
+// @Failure 400 {object} utils.Response "请求参数错误或目标不是文件夹"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "无权访问目标文件夹"
+// @Failure 404 {object} utils.Response "剪贴板为空或目标文件夹不存在"
+// @Failure 409 {object} utils.Response{data=filesvc.ClipboardConflictError} "存在冲突，未做任何修改"
+// @Router /api/v1/files/clipboard/paste [post]
+func (h *FileClipboardHandler) PasteClipboard(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	var req PasteClipboardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+		return
+	}
+
+	result, err := h.service.Paste(c.Request.Context(), userID, req.ParentID)
+	if err != nil {
+		respondServiceError(c, err, "粘贴失败")
+		return
+	}
+
+	utils.Success(c, result)
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+	"cloudpan/internal/service/file"
+)
+
+// MockClipboardService 模拟服务端剪贴板服务
+type MockClipboardService struct {
+	mock.Mock
+}
+
+func (m *MockClipboardService) Get(ctx context.Context, userID uint) (*file.ClipboardView, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*file.ClipboardView), args.Error(1)
+}
+
+func (m *MockClipboardService) Set(ctx context.Context, userID uint, operation string, fileIDs []uint) (*file.ClipboardView, error) {
+	args := m.Called(ctx, userID, operation, fileIDs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*file.ClipboardView), args.Error(1)
+}
+
+func (m *MockClipboardService) Clear(ctx context.Context, userID uint) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockClipboardService) Paste(ctx context.Context, userID uint, targetParentID *uint) (*file.PasteResult, error) {
+	args := m.Called(ctx, userID, targetParentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*file.PasteResult), args.Error(1)
+}
+
+func setupFileClipboardRouter(service *MockClipboardService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler := NewFileClipboardHandler(service, zap.NewNop())
+	authed := router.Group("", func(c *gin.Context) {
+		c.Set("user_id", uint64(7))
+	})
+	authed.GET("/files/clipboard", handler.GetClipboard)
+	authed.PUT("/files/clipboard", handler.SetClipboard)
+	authed.DELETE("/files/clipboard", handler.ClearClipboard)
+	authed.POST("/files/clipboard/paste", handler.PasteClipboard)
+	return router
+}
+
+func TestFileClipboardHandler_SetAndGet(t *testing.T) {
+	service := new(MockClipboardService)
+	view := &file.ClipboardView{Operation: file.ClipboardMove, Items: []*models.File{{Name: "a.txt"}}, Missing: []uint{}}
+	service.On("Set", mock.Anything, uint(7), file.ClipboardMove, []uint{2, 3}).Return(view, nil)
+	service.On("Get", mock.Anything, uint(7)).Return(view, nil)
+	service.On("Clear", mock.Anything, uint(7)).Return(nil)
+	router := setupFileClipboardRouter(service)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/files/clipboard", strings.NewReader(`{"operation":"move","file_ids":[2,3]}`)))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/files/clipboard", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"operation":"move"`)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/files/clipboard", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/files/clipboard", strings.NewReader(`{"file_ids":[2]}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	service.AssertExpectations(t)
+}
+
+func TestFileClipboardHandler_PasteClipboard(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		service := new(MockClipboardService)
+		service.On("Paste", mock.Anything, uint(7), mock.MatchedBy(func(parentID *uint) bool {
+			return parentID != nil && *parentID == 5
+		})).Return(&file.PasteResult{Operation: file.ClipboardCopy, Files: []*models.File{{Name: "a.txt"}}}, nil)
+
+		w := httptest.NewRecorder()
+		setupFileClipboardRouter(service).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/files/clipboard/paste", strings.NewReader(`{"parent_id":5}`)))
+
+		require.Equal(t, http.StatusOK, w.Code)
+		service.AssertExpectations(t)
+	})
+
+	t.Run("conflict", func(t *testing.T) {
+		service := new(MockClipboardService)
+		service.On("Paste", mock.Anything, uint(7), (*uint)(nil)).Return(nil, &file.ClipboardConflictError{Conflicts: []file.ClipboardConflict{
+			{FileID: 2, Name: "a.txt", Reason: file.ClipboardConflictNameExists, Message: "目标文件夹下已存在同名文件"},
+		}})
+
+		w := httptest.NewRecorder()
+		setupFileClipboardRouter(service).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/files/clipboard/paste", strings.NewReader(`{}`)))
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Contains(t, w.Body.String(), `"reason":"name_exists"`)
+	})
+
+	t.Run("empty clipboard", func(t *testing.T) {
+		service := new(MockClipboardService)
+		service.On("Paste", mock.Anything, uint(7), (*uint)(nil)).
+			Return(nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "剪贴板为空"))
+
+		w := httptest.NewRecorder()
+		setupFileClipboardRouter(service).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/files/clipboard/paste", strings.NewReader(`{}`)))
+
+		assert.Equal(t, utils.CodeNotFound, decodeShareResponse(t, w).Code)
+	})
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/service/automation"
+	"cloudpan/internal/service/file"
+	"cloudpan/internal/service/jobs"
+)
+
+// DirectUploadHandler 浏览器直传处理器
+type DirectUploadHandler struct {
+	service        file.DirectUploadService
+	allowedOrigins []string
+	scans          file.ScanScheduler
+	previews       file.PreviewScheduler
+	rules          automation.UploadDispatcher
+	logger         *zap.Logger
+}
+
+// NewDirectUploadHandler 创建浏览器直传处理器
+//
+// allowedOrigins 为允许申请直传凭证的页面来源，应与存储桶的CORS规则保持一致，为空时不限制
+func NewDirectUploadHandler(service file.DirectUploadService, allowedOrigins []string, logger *zap.Logger) *DirectUploadHandler {
+	origins := make([]string, 0, len(allowedOrigins))
+	for _, origin := range allowedOrigins {
+		if origin = strings.TrimSuffix(strings.TrimSpace(origin), "/"); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	return &DirectUploadHandler{
+		service:        service,
+		allowedOrigins: origins,
+		logger:         logger,
+	}
+}
+
+// SetScanScheduler 设置病毒扫描任务调度，未设置时上传的文件一直处于待扫描状态
+func (h *DirectUploadHandler) SetScanScheduler(scans file.ScanScheduler) {
+	h.scans = scans
+}
+
+// SetPreviewScheduler 设置预览生成任务调度，未设置时上传完成后不生成预览
+func (h *DirectUploadHandler) SetPreviewScheduler(previews file.PreviewScheduler) {
+	h.previews = previews
+}
+
+// SetUploadDispatcher 设置文件夹规则触发，未设置时上传完成后不执行文件夹规则
+func (h *DirectUploadHandler) SetUploadDispatcher(rules automation.UploadDispatcher) {
+	h.rules = rules
+}
+
+// InitiateDirectUpload 申请直传凭证
+//
+// @Summary 申请浏览器直传凭证
+// @Description 为大文件签发预签名POST表单，浏览器将form.fields连同文件(file字段放在最后)以multipart/form-data提交到form.url，上传成功后调用完成接口。凭证只能上传声明大小和SHA-256的这一个对象
+// @Tags 文件
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body file.DirectUploadRequest true "上传文件信息"
+// @Success 200 {object} utils.Response{data=file.DirectUploadTicket} "直传凭证"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "来源不允许或存储空间不足"
+// @Failure 404 {object} utils.Response "目标文件夹不存在"
+// @Router /api/v1/files/direct-uploads [post]
+func (h *DirectUploadHandler) InitiateDirectUpload(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+	if !h.originAllowed(c.GetHeader("Origin")) {
+		h.logger.Warn("Direct upload requested from disallowed origin",
+			zap.Uint("user_id", userID),
+			zap.String("origin", c.GetHeader("Origin")),
+			zap.String("ip", c.ClientIP()))
+		utils.ErrorWithMessage(c, utils.CodeForbidden, "当前来源不允许直传上传")
+		return
+	}
+
+	var req file.DirectUploadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+		return
+	}
+
+	ticket, err := h.service.Initiate(c.Request.Context(), userID, &req)
+	if err != nil {
+		respondServiceError(c, err, "申请直传凭证失败")
+		return
+	}
+
+	h.logger.Info("Direct upload ticket issued",
+		zap.Uint("user_id", userID),
+		zap.String("upload_id", ticket.UploadID),
+		zap.Int64("size", req.Size),
+		zap.String("ip", c.ClientIP()))
+	utils.Success(c, ticket)
+}
+
+// CompleteDirectUpload 完成直传
+//
+// @Summary 完成浏览器直传
+// @Description 浏览器上传到对象存储成功后调用，服务端核对对象大小和SHA-256后登记文件。可安全重试，对象尚未出现时返回验证错误
+// @Description 病毒扫描和预览生成在后台进行，进度见返回文件的processing字段
+// @Tags 文件
+// @Produce json
+// @Security BearerAuth
+// @Param upload_id path string true "上传任务ID"
+// @Success 200 {object} utils.Response{data=models.File} "登记完成的文件"
+// @Failure 400 {object} utils.Response "对象尚未上传或大小/SHA-256校验失败"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "无权操作或上传任务已失效"
+// @Failure 404 {object} utils.Response "上传任务不存在"
+// @Router /api/v1/files/direct-uploads/{upload_id}/complete [post]
+func (h *DirectUploadHandler) CompleteDirectUpload(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	uploaded, err := h.service.Complete(c.Request.Context(), userID, c.Param("upload_id"))
+	if err != nil {
+		respondServiceError(c, err, "完成直传失败")
+		return
+	}
+
+	h.logger.Info("Direct upload confirmed",
+		zap.Uint("user_id", userID),
+		zap.Uint("file_id", uploaded.ID),
+		zap.String("ip", c.ClientIP()))
+	if h.scans != nil {
+		h.scans.Schedule(c.Request.Context(), uploaded)
+	}
+	if h.previews != nil {
+		h.previews.Schedule(c.Request.Context(), uploaded, jobs.PriorityInteractive)
+	}
+	if h.rules != nil {
+		h.rules.FileUploaded(uploaded)
+	}
+	utils.Success(c, uploaded)
+}
+
+// originAllowed 检查请求来源，非浏览器请求(无Origin)不受限制
+func (h *DirectUploadHandler) originAllowed(origin string) bool {
+	if len(h.allowedOrigins) == 0 || origin == "" {
+		return true
+	}
+	return slices.Contains(h.allowedOrigins, strings.TrimSuffix(origin, "/"))
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/storage"
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+	"cloudpan/internal/service/file"
+)
+
+// MockDirectUploadService 模拟直传服务
+type MockDirectUploadService struct {
+	mock.Mock
+}
+
+func (m *MockDirectUploadService) Initiate(ctx context.Context, userID uint, req *file.DirectUploadRequest) (*file.DirectUploadTicket, error) {
+	args := m.Called(ctx, userID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*file.DirectUploadTicket), args.Error(1)
+}
+
+func (m *MockDirectUploadService) Complete(ctx context.Context, userID uint, uploadID string) (*models.File, error) {
+	args := m.Called(ctx, userID, uploadID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.File), args.Error(1)
+}
+
+func setupDirectUploadRouter(service *MockDirectUploadService, origins []string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler := NewDirectUploadHandler(service, origins, zap.NewNop())
+	authed := router.Group("", func(c *gin.Context) {
+		c.Set("user_id", uint64(7))
+	})
+	authed.POST("/files/direct-uploads", handler.InitiateDirectUpload)
+	authed.POST("/files/direct-uploads/:upload_id/complete", handler.CompleteDirectUpload)
+	return router
+}
+
+func TestDirectUploadHandler_Initiate(t *testing.T) {
+	body := `{"name":"movie.mp4","size":500,"sha256":"` + strings.Repeat("ab", 32) + `"}`
+
+	t.Run("success", func(t *testing.T) {
+		service := new(MockDirectUploadService)
+		service.On("Initiate", mock.Anything, uint(7), mock.MatchedBy(func(req *file.DirectUploadRequest) bool {
+			return req.Name == "movie.mp4" && req.Size == 500
+		})).Return(&file.DirectUploadTicket{UploadID: "up-1", Form: &storage.PresignedPost{URL: "https://bucket.example.com/"}}, nil)
+
+		req := httptest.NewRequest(http.MethodPost, "/files/direct-uploads", strings.NewReader(body))
+		req.Header.Set("Origin", "https://pan.example.com/")
+		w := httptest.NewRecorder()
+		setupDirectUploadRouter(service, []string{"https://pan.example.com"}).ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, utils.CodeSuccess, decodeShareResponse(t, w).Code)
+		service.AssertExpectations(t)
+	})
+
+	t.Run("disallowed origin", func(t *testing.T) {
+		service := new(MockDirectUploadService)
+		req := httptest.NewRequest(http.MethodPost, "/files/direct-uploads", strings.NewReader(body))
+		req.Header.Set("Origin", "https://evil.example.com")
+		w := httptest.NewRecorder()
+		setupDirectUploadRouter(service, []string{"https://pan.example.com"}).ServeHTTP(w, req)
+
+		assert.Equal(t, utils.CodeForbidden, decodeShareResponse(t, w).Code)
+		service.AssertNotCalled(t, "Initiate", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("quota exceeded", func(t *testing.T) {
+		service := new(MockDirectUploadService)
+		service.On("Initiate", mock.Anything, uint(7), mock.Anything).
+			Return(nil, pkgErrors.WrapError(pkgErrors.ErrQuotaExceeded, "存储空间不足"))
+
+		w := httptest.NewRecorder()
+		setupDirectUploadRouter(service, nil).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/files/direct-uploads", strings.NewReader(body)))
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Equal(t, utils.CodeQuotaExceeded, decodeShareResponse(t, w).Code)
+	})
+}
+
+func TestDirectUploadHandler_Complete(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		service := new(MockDirectUploadService)
+		uploaded := &models.File{Name: "movie.mp4", Status: "active"}
+		uploaded.ID = 42
+		service.On("Complete", mock.Anything, uint(7), "up-1").Return(uploaded, nil)
+
+		w := httptest.NewRecorder()
+		setupDirectUploadRouter(service, nil).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/files/direct-uploads/up-1/complete", nil))
+
+		require.Equal(t, http.StatusOK, w.Code)
+		service.AssertExpectations(t)
+	})
+
+	t.Run("verification failed", func(t *testing.T) {
+		service := new(MockDirectUploadService)
+		service.On("Complete", mock.Anything, uint(7), "up-1").
+			Return(nil, pkgErrors.WrapError(pkgErrors.ErrValidationFailed, "上传文件SHA-256校验失败"))
+
+		w := httptest.NewRecorder()
+		setupDirectUploadRouter(service, nil).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/files/direct-uploads/up-1/complete", nil))
+
+		assert.Equal(t, utils.CodeValidationError, decodeShareResponse(t, w).Code)
+	})
+}
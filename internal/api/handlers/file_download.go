@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+	"cloudpan/internal/service/file"
+)
+
+// FileDownloadHandler 文件下载处理器
+type FileDownloadHandler struct {
+	fileAccess
+	downloadService file.DownloadService
+	logger          *zap.Logger
+}
+
+// NewFileDownloadHandler 创建文件下载处理器
+func NewFileDownloadHandler(downloadService file.DownloadService, logger *zap.Logger) *FileDownloadHandler {
+	return &FileDownloadHandler{
+		downloadService: downloadService,
+		logger:          logger,
+	}
+}
+
+// Download 下载文件，支持HTTP Range分段和断点续传
+//
+// @Summary 下载文件
+// @Description 从当前存储后端流式下载文件。支持Range请求(单段和多段)、If-Range和条件请求；只有完整下载或从头开始的Range请求计入下载次数，续传请求不重复计数。public文件对所有登录用户开放，其他文件只有所有者和获得read授权(含上级文件夹上的授权)的用户可以下载
+// @Tags 文件
+// @Produce octet-stream
+// @Security BearerAuth
+// @Param id path int true "文件ID"
+// @Param Range header string false "字节范围，如 bytes=0-1023"
+// @Success 200 {file} file "文件内容"
+// @Success 206 {file} file "部分文件内容"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "无权下载、文件已归档或存储不可用"
+// @Failure 404 {object} utils.Response "文件不存在"
+// @Failure 416 {string} string "请求范围无效"
+// @Failure 500 {object} utils.Response "内部服务器错误"
+// @Router /api/v1/files/{id}/download [get]
+func (h *FileDownloadHandler) Download(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	fileID, ok := parseIDParam(c, "id")
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "文件ID格式错误")
+		return
+	}
+
+	actingUserID, ok := h.actingUser(c, userID, fileID, models.FilePermissionRead)
+	if !ok {
+		return
+	}
+
+	download, err := h.downloadService.Open(ctx, actingUserID, fileID)
+	if err != nil {
+		h.logger.Warn("Failed to open file download",
+			zap.Uint("user_id", userID),
+			zap.Uint("file_id", fileID),
+			zap.Error(err),
+			zap.String("ip", c.ClientIP()))
+		respondServiceError(c, err, "下载文件失败")
+		return
+	}
+	defer download.Content.Close()
+
+	c.Header("Content-Type", download.MimeType)
+	c.Header("Content-Disposition", contentDisposition(download.Name))
+	c.Header("X-Content-Type-Options", "nosniff")
+	if download.ETag != "" {
+		c.Header("ETag", download.ETag)
+	}
+
+	rangeHeader := c.GetHeader("Range")
+	if isInitialDownload(c.Request.Method, rangeHeader) {
+		h.downloadService.RecordDownload(ctx, download)
+	}
+
+	// ServeContent处理Range、If-Range和条件请求，按需定位并读取存储对象
+	http.ServeContent(c.Writer, c.Request, download.Name, download.ModTime, download.Content)
+
+	h.logger.Info("File downloaded",
+		zap.Uint("user_id", userID),
+		zap.Uint("file_id", fileID),
+		zap.Int("status", c.Writer.Status()),
+		zap.String("range", rangeHeader),
+		zap.String("ip", c.ClientIP()))
+}
+
+// isInitialDownload 判断请求是否为一次新的下载
+//
+// 没有Range或从0字节开始的GET请求才计数，断点续传的后续分段不重复计数
+func isInitialDownload(method, rangeHeader string) bool {
+	if method != http.MethodGet {
+		return false
+	}
+	rangeHeader = strings.TrimSpace(rangeHeader)
+	return rangeHeader == "" || strings.HasPrefix(rangeHeader, "bytes=0-")
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/service/file"
+)
+
+// MockFileDownloadService 模拟文件下载服务
+type MockFileDownloadService struct {
+	mock.Mock
+}
+
+func (m *MockFileDownloadService) Open(ctx context.Context, userID, fileID uint) (*file.FileDownload, error) {
+	args := m.Called(ctx, userID, fileID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*file.FileDownload), args.Error(1)
+}
+
+func (m *MockFileDownloadService) RecordDownload(ctx context.Context, download *file.FileDownload) {
+	m.Called(ctx, download)
+}
+
+// nopSeekCloser 为strings.Reader补充Close
+type nopSeekCloser struct {
+	*strings.Reader
+}
+
+func (nopSeekCloser) Close() error { return nil }
+
+func setupFileDownloadRouter(service *MockFileDownloadService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler := NewFileDownloadHandler(service, zap.NewNop())
+	router.GET("/files/:id/download", func(c *gin.Context) {
+		c.Set("user_id", uint64(7))
+	}, handler.Download)
+	return router
+}
+
+func newTestFileDownload() *file.FileDownload {
+	return &file.FileDownload{
+		FileID:   5,
+		Name:     "报告.txt",
+		MimeType: "text/plain",
+		Size:     11,
+		ModTime:  time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		ETag:     `"abc"`,
+		Content:  nopSeekCloser{strings.NewReader("hello world")},
+	}
+}
+
+func TestFileDownloadHandler_Download(t *testing.T) {
+	t.Run("full download", func(t *testing.T) {
+		service := new(MockFileDownloadService)
+		download := newTestFileDownload()
+		service.On("Open", mock.Anything, uint(7), uint(5)).Return(download, nil)
+		service.On("RecordDownload", mock.Anything, download).Return()
+
+		w := httptest.NewRecorder()
+		setupFileDownloadRouter(service).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/files/5/download", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "hello world", w.Body.String())
+		assert.Equal(t, "text/plain", w.Header().Get("Content-Type"))
+		assert.Equal(t, "11", w.Header().Get("Content-Length"))
+		assert.Equal(t, "bytes", w.Header().Get("Accept-Ranges"))
+		assert.Equal(t, `"abc"`, w.Header().Get("ETag"))
+		assert.Contains(t, w.Header().Get("Content-Disposition"), "filename*=UTF-8''%E6%8A%A5%E5%91%8A.txt")
+		service.AssertExpectations(t)
+	})
+
+	t.Run("resumed range is not counted", func(t *testing.T) {
+		service := new(MockFileDownloadService)
+		service.On("Open", mock.Anything, uint(7), uint(5)).Return(newTestFileDownload(), nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/files/5/download", nil)
+		req.Header.Set("Range", "bytes=6-")
+		w := httptest.NewRecorder()
+		setupFileDownloadRouter(service).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusPartialContent, w.Code)
+		assert.Equal(t, "world", w.Body.String())
+		assert.Equal(t, "bytes 6-10/11", w.Header().Get("Content-Range"))
+		service.AssertNotCalled(t, "RecordDownload", mock.Anything, mock.Anything)
+	})
+
+	t.Run("range from start is counted", func(t *testing.T) {
+		service := new(MockFileDownloadService)
+		download := newTestFileDownload()
+		service.On("Open", mock.Anything, uint(7), uint(5)).Return(download, nil)
+		service.On("RecordDownload", mock.Anything, download).Return()
+
+		req := httptest.NewRequest(http.MethodGet, "/files/5/download", nil)
+		req.Header.Set("Range", "bytes=0-4")
+		w := httptest.NewRecorder()
+		setupFileDownloadRouter(service).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusPartialContent, w.Code)
+		assert.Equal(t, "hello", w.Body.String())
+		service.AssertExpectations(t)
+	})
+
+	t.Run("unsatisfiable range", func(t *testing.T) {
+		service := new(MockFileDownloadService)
+		service.On("Open", mock.Anything, uint(7), uint(5)).Return(newTestFileDownload(), nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/files/5/download", nil)
+		req.Header.Set("Range", "bytes=20-")
+		w := httptest.NewRecorder()
+		setupFileDownloadRouter(service).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusRequestedRangeNotSatisfiable, w.Code)
+	})
+
+	t.Run("permission denied", func(t *testing.T) {
+		service := new(MockFileDownloadService)
+		service.On("Open", mock.Anything, uint(7), uint(5)).
+			Return(nil, pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, "无权下载该文件"))
+
+		w := httptest.NewRecorder()
+		setupFileDownloadRouter(service).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/files/5/download", nil))
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Equal(t, utils.CodeForbidden, decodeShareResponse(t, w).Code)
+		service.AssertNotCalled(t, "RecordDownload", mock.Anything, mock.Anything)
+	})
+
+	t.Run("invalid id", func(t *testing.T) {
+		service := new(MockFileDownloadService)
+		w := httptest.NewRecorder()
+		setupFileDownloadRouter(service).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/files/abc/download", nil))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
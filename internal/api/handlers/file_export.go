@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/service/file"
+)
+
+// FileExportHandler 文件夹导出处理器
+type FileExportHandler struct {
+	exporter *file.FolderExporter
+	logger   *zap.Logger
+}
+
+// NewFileExportHandler 创建文件夹导出处理器
+//
+// 权限由导出器逐个条目检查，获得授权的用户导出他人的文件夹需在导出器上设置授权服务
+func NewFileExportHandler(exporter *file.FolderExporter, logger *zap.Logger) *FileExportHandler {
+	return &FileExportHandler{
+		exporter: exporter,
+		logger:   logger,
+	}
+}
+
+// DownloadFolder 将文件夹打包为ZIP下载
+//
+// @Summary 下载文件夹
+// @Description 将文件夹及其子项实时打包为ZIP流式下载，不生成临时文件。每个条目单独检查权限，
+// @Description 无权访问、上传未完成或已归档的条目被跳过，跳过的数量见响应头X-Skipped-Entries。
+// @Description 文件数或总大小超过上限时在开始下载前返回错误(code=1033)，data中包含超出的限制和上限
+// @Tags 文件
+// @Produce application/zip
+// @Security BearerAuth
+// @Param id path int true "文件夹ID"
+// @Success 200 {file} file "ZIP文件流"
+// @Header 200 {integer} X-Skipped-Entries "跳过的条目数"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response{data=file.ExportLimitError} "无权访问或超出打包下载上限"
+// @Failure 404 {object} utils.Response "文件夹不存在"
+// @Failure 429 {object} utils.Response "同时进行的下载过多"
+// @Failure 500 {object} utils.Response "内部服务器错误"
+// @Router /api/v1/folders/{id}/download [get]
+func (h *FileExportHandler) DownloadFolder(c *gin.Context) {
+	h.streamFolder(c)
+}
+
+// ExportFolder 以ZIP格式流式导出文件夹
+//
+// @Summary 导出文件夹
+// @Description 将文件夹及其子项打包为ZIP流式下载，与下载文件夹相同。每个用户同时进行的导出数量、单次导出的文件数和总大小受配置限制
+// @Tags 文件
+// @Produce application/zip
+// @Security BearerAuth
+// @Param id path int true "文件夹ID"
+// @Success 200 {file} file "ZIP文件流"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "无权访问或超出导出限制"
+// @Failure 404 {object} utils.Response "文件夹不存在"
+// @Failure 429 {object} utils.Response "同时进行的导出过多"
+// @Failure 500 {object} utils.Response "内部服务器错误"
+// @Router /api/v1/files/{id}/export [get]
+func (h *FileExportHandler) ExportFolder(c *gin.Context) {
+	h.streamFolder(c)
+}
+
+// streamFolder 校验后以ZIP格式流式写出文件夹
+func (h *FileExportHandler) streamFolder(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	folderID, ok := parseIDParam(c, "id")
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "文件夹ID格式错误")
+		return
+	}
+
+	export, err := h.exporter.Begin(ctx, userID, folderID)
+	if err != nil {
+		h.logger.Warn("Failed to start folder export",
+			zap.Uint("user_id", userID),
+			zap.Uint("folder_id", folderID),
+			zap.Error(err),
+			zap.String("ip", c.ClientIP()))
+		respondServiceError(c, err, "文件夹导出失败")
+		return
+	}
+	defer export.Close()
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", contentDisposition(export.Folder().Name+".zip"))
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("X-Skipped-Entries", strconv.Itoa(export.Stats().Skipped))
+	c.Status(200)
+
+	// 响应头已发出，之后的错误只能记录日志并中断连接
+	stats, err := export.WriteZip(ctx, c.Writer)
+	if err != nil {
+		h.logger.Error("Folder export interrupted",
+			zap.Uint("user_id", userID),
+			zap.Uint("folder_id", folderID),
+			zap.Int("files", stats.Files),
+			zap.Int64("bytes", stats.TotalSize),
+			zap.Error(err),
+			zap.String("ip", c.ClientIP()))
+		c.Abort()
+		return
+	}
+
+	h.logger.Info("Folder exported",
+		zap.Uint("user_id", userID),
+		zap.Uint("folder_id", folderID),
+		zap.Int("files", stats.Files),
+		zap.Int("folders", stats.Folders),
+		zap.Int("skipped", stats.Skipped),
+		zap.Int64("bytes", stats.TotalSize),
+		zap.String("ip", c.ClientIP()))
+}
+
+// contentDisposition 生成附件下载响应头，非ASCII文件名使用RFC 5987编码
+func contentDisposition(filename string) string {
+	fallback := strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e || r == '"' || r == '\\' {
+			return '_'
+		}
+		return r
+	}, filename)
+	return fmt.Sprintf(`attachment; filename="%s"; filename*=UTF-8''%s`, fallback, url.PathEscape(filename))
+}
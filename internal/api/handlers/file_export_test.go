@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	basemodels "cloudpan/internal/pkg/database/models"
+	"cloudpan/internal/pkg/storage"
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+	"cloudpan/internal/service/file"
+)
+
+// exportFileRepository 导出测试用的内存文件仓储
+type exportFileRepository struct {
+	files    map[uint]*models.File
+	children map[uint][]*models.File
+}
+
+func (r *exportFileRepository) GetByID(_ context.Context, id uint) (*models.File, error) {
+	if f, ok := r.files[id]; ok {
+		return f, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *exportFileRepository) GetByUUID(context.Context, string) (*models.File, error) {
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *exportFileRepository) ListChildren(_ context.Context, parentID uint) ([]*models.File, error) {
+	return r.children[parentID], nil
+}
+
+func (r *exportFileRepository) UpdateChecksum(context.Context, uint, string, time.Time) error {
+	return nil
+}
+
+func (r *exportFileRepository) ClearChecksums(context.Context, []uint) error {
+	return nil
+}
+
+func (r *exportFileRepository) Create(context.Context, *models.File) error {
+	return nil
+}
+
+func (r *exportFileRepository) CompleteUpload(context.Context, uint, int64) (bool, error) {
+	return false, nil
+}
+
+func (r *exportFileRepository) FailUpload(context.Context, uint) error {
+	return nil
+}
+
+func (r *exportFileRepository) UpdateUploadMetadata(context.Context, uint, *basemodels.JSONMap) error {
+	return nil
+}
+
+func (r *exportFileRepository) DeleteUpload(context.Context, *models.File) (bool, error) {
+	return false, nil
+}
+
+func (r *exportFileRepository) ListStaleUploads(context.Context, time.Time, int) ([]*models.File, error) {
+	return nil, nil
+}
+
+func (r *exportFileRepository) ListArchiveCandidates(context.Context, time.Time, int64, int64, int) ([]*models.File, error) {
+	return nil, nil
+}
+
+func (r *exportFileRepository) MarkArchived(context.Context, uint, string, time.Time) error {
+	return nil
+}
+
+func (r *exportFileRepository) UpdateRestoreState(context.Context, uint, *time.Time, *time.Time) error {
+	return nil
+}
+
+func (r *exportFileRepository) RecordDownload(context.Context, uint, time.Time) error {
+	return nil
+}
+
+func (r *exportFileRepository) UpdatePreviewURLs(context.Context, uint, *string, *string) error {
+	return nil
+}
+
+func (r *exportFileRepository) UpdateScanStatus(context.Context, uint, string) error {
+	return nil
+}
+
+func (r *exportFileRepository) UpdatePreviewStatus(context.Context, uint, string) error {
+	return nil
+}
+
+func (r *exportFileRepository) UpdateTags(context.Context, uint, *string) error {
+	return nil
+}
+
+func (r *exportFileRepository) UpdateMetadata(context.Context, uint, *basemodels.JSONMap) error {
+	return nil
+}
+
+func setupFileExportRouter(t *testing.T, limiter *file.ExportLimiter) *gin.Engine {
+	t.Helper()
+	store, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, store.Put(context.Background(), "files/2", strings.NewReader("hello"), 5))
+
+	folder := &models.File{UserID: 7, Name: "报告", IsFolder: true}
+	folder.ID = 1
+	path := "files/2"
+	doc := &models.File{UserID: 7, ParentID: &folder.ID, Name: "a.txt", Size: 5, StoragePath: &path}
+	doc.ID = 2
+	repo := &exportFileRepository{
+		files:    map[uint]*models.File{1: folder, 2: doc},
+		children: map[uint][]*models.File{1: {doc}},
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler := NewFileExportHandler(file.NewFolderExporter(repo, store, limiter, file.ExportOptions{}, zap.NewNop()), zap.NewNop())
+	router.GET("/files/:id/export", func(c *gin.Context) {
+		c.Set("user_id", uint64(7))
+		handler.ExportFolder(c)
+	})
+	return router
+}
+
+func TestExportFolder_StreamsZip(t *testing.T) {
+	router := setupFileExportRouter(t, nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/files/1/export", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/zip", w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="__.zip"; filename*=UTF-8''%E6%8A%A5%E5%91%8A.zip`, w.Header().Get("Content-Disposition"))
+
+	reader, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	require.NoError(t, err)
+	names := make([]string, 0, len(reader.File))
+	for _, f := range reader.File {
+		names = append(names, f.Name)
+	}
+	assert.Equal(t, []string{"报告/", "报告/a.txt"}, names)
+}
+
+func TestExportFolder_Errors(t *testing.T) {
+	t.Run("invalid id", func(t *testing.T) {
+		router := setupFileExportRouter(t, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/files/abc/export", nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("regular file", func(t *testing.T) {
+		router := setupFileExportRouter(t, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/files/2/export", nil))
+
+		var resp utils.Response
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, utils.CodeValidationError, resp.Code)
+	})
+
+	t.Run("too many concurrent exports", func(t *testing.T) {
+		limiter := file.NewExportLimiter(1, 10)
+		release, err := limiter.Acquire(7)
+		require.NoError(t, err)
+		defer release()
+
+		router := setupFileExportRouter(t, limiter)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/files/1/export", nil))
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	})
+}
+
+func TestDownloadFolder(t *testing.T) {
+	store, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, store.Put(context.Background(), "files/2", strings.NewReader("hello"), 5))
+
+	folder := &models.File{UserID: 7, Name: "docs", IsFolder: true}
+	folder.ID = 1
+	path := "files/2"
+	doc := &models.File{UserID: 7, ParentID: &folder.ID, Name: "a.txt", Size: 5, StoragePath: &path}
+	doc.ID = 2
+	other := &models.File{UserID: 9, ParentID: &folder.ID, Name: "b.txt", Size: 5, StoragePath: &path}
+	other.ID = 3
+	repo := &exportFileRepository{
+		files:    map[uint]*models.File{1: folder, 2: doc, 3: other},
+		children: map[uint][]*models.File{1: {doc, other}},
+	}
+
+	setup := func(options file.ExportOptions) *gin.Engine {
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		handler := NewFileExportHandler(file.NewFolderExporter(repo, store, nil, options, zap.NewNop()), zap.NewNop())
+		router.GET("/folders/:id/download", func(c *gin.Context) {
+			c.Set("user_id", uint64(7))
+			handler.DownloadFolder(c)
+		})
+		return router
+	}
+
+	t.Run("skips entries of other owners", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		setup(file.ExportOptions{}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/folders/1/download", nil))
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "1", w.Header().Get("X-Skipped-Entries"))
+		reader, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+		require.NoError(t, err)
+		require.Len(t, reader.File, 2)
+		assert.Equal(t, "docs/a.txt", reader.File[1].Name)
+	})
+
+	t.Run("size limit exceeded", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		setup(file.ExportOptions{MaxTotalSize: 4}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/folders/1/download", nil))
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		var resp struct {
+			Code utils.ResponseCode    `json:"code"`
+			Data file.ExportLimitError `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, utils.CodeDownloadTooLarge, resp.Code)
+		assert.Equal(t, file.ExportLimitError{Limit: file.ExportLimitSize, Max: 4, Actual: 5}, resp.Data)
+		assert.Empty(t, w.Header().Get("Content-Disposition"))
+	})
+}
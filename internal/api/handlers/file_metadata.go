@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+	"cloudpan/internal/service/audit"
+	filesvc "cloudpan/internal/service/file"
+)
+
+// bulkMetadataAction gin把路径中的冒号解析为参数，/files/metadata:bulk 注册后参数bulk的值为":bulk"
+const bulkMetadataAction = ":bulk"
+
+// FileMetadataHandler 批量编辑文件标签和属性的处理器
+type FileMetadataHandler struct {
+	fileAccess
+	securityAudit
+	service filesvc.MetadataService
+	logger  *zap.Logger
+}
+
+// NewFileMetadataHandler 创建批量编辑文件标签和属性的处理器
+func NewFileMetadataHandler(service filesvc.MetadataService, logger *zap.Logger) *FileMetadataHandler {
+	return &FileMetadataHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// BulkFileMetadataRequest 批量编辑文件标签和属性请求
+type BulkFileMetadataRequest struct {
+	IDs        []uint             `json:"ids" binding:"required"` // 文件ID，最多500个
+	AddTags    []string           `json:"add_tags"`               // 添加的标签，文件上已有的标签跳过
+	TagColor   string             `json:"tag_color"`              // 新添加标签的颜色，如#1890ff
+	RemoveTags []string           `json:"remove_tags"`            // 删除的标签，文件上没有的标签跳过
+	Properties map[string]*string `json:"properties"`             // 设置的属性，值为null时删除该属性
+}
+
+// BulkFileMetadataResult 批量编辑文件标签和属性结果
+type BulkFileMetadataResult struct {
+	Total     int                `json:"total"`     // 处理的文件数(去重后)
+	Succeeded int                `json:"succeeded"` // 成功数
+	Failed    int                `json:"failed"`    // 失败数
+	Changed   int                `json:"changed"`   // 实际有修改的文件数
+	Items     []*BatchItemResult `json:"items"`     // 各文件结果，顺序与请求一致，成功时data为修改后的标签和属性
+}
+
+// BulkUpdateMetadata 批量编辑文件标签和属性
+//
+// @Summary 批量编辑文件标签和属性
+// @Description 对多个文件添加和删除标签、设置和删除自定义属性(属性值为null时删除)。文件每50个在一个事务中处理，某个文件失败不影响其他文件，逐项返回结果和失败原因；提交失败时整批文件返回失败。重复的ID只处理一次，单次最多500个。获得授权的用户需要对文件有写入权限。有修改时只记录一条汇总的操作日志
+// @Tags 文件
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body BulkFileMetadataRequest true "文件ID和要修改的标签、属性"
+// @Success 200 {object} utils.Response{data=BulkFileMetadataResult} "各文件的结果"
+// @Failure 400 {object} utils.Response "请求参数错误、没有要修改的内容或文件数量超出限制"
+// @Failure 401 {object} utils.Response "未认证"
+// @Router /api/v1/files/metadata:bulk [post]
+func (h *FileMetadataHandler) BulkUpdateMetadata(c *gin.Context) {
+	if c.Param("bulk") != bulkMetadataAction {
+		utils.ErrorWithMessage(c, utils.CodeNotFound, "接口不存在")
+		return
+	}
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	var req BulkFileMetadataRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+		return
+	}
+	ids := uniqueIDs(req.IDs)
+	if len(ids) == 0 {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请选择要修改的文件")
+		return
+	}
+	if len(ids) > filesvc.MaxBulkMetadataFiles {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "单次最多修改"+strconv.Itoa(filesvc.MaxBulkMetadataFiles)+"个文件")
+		return
+	}
+
+	// 先逐个检查写入权限，无权访问的文件不进入事务
+	ctx := c.Request.Context()
+	items := make([]*BatchItemResult, len(ids))
+	targets := make([]filesvc.MetadataTarget, 0, len(ids))
+	positions := make([]int, 0, len(ids))
+	for i, id := range ids {
+		actingUserID, err := h.resolveActingUser(ctx, userID, id, models.FilePermissionWrite)
+		if err != nil {
+			item := &BatchItemResult{ID: id}
+			item.Reason, item.Message = batchFailure(err, "检查文件权限失败")
+			items[i] = item
+			continue
+		}
+		targets = append(targets, filesvc.MetadataTarget{FileID: id, UserID: actingUserID})
+		positions = append(positions, i)
+	}
+
+	outcomes, err := h.service.BulkUpdate(ctx, targets, &filesvc.BulkMetadataRequest{
+		AddTags:    req.AddTags,
+		TagColor:   req.TagColor,
+		RemoveTags: req.RemoveTags,
+		Properties: req.Properties,
+	})
+	if err != nil {
+		respondServiceError(c, err, "批量修改文件元数据失败")
+		return
+	}
+
+	result := &BulkFileMetadataResult{Total: len(ids)}
+	var changedIDs []uint
+	for i, outcome := range outcomes {
+		item := &BatchItemResult{ID: outcome.FileID}
+		if outcome.Err == nil {
+			item.Success, item.Data = true, outcome.Metadata
+			if outcome.Metadata.Changed {
+				changedIDs = append(changedIDs, outcome.FileID)
+			}
+		} else {
+			item.Reason, item.Message = batchFailure(outcome.Err, "修改文件元数据失败")
+			if item.Reason == BatchReasonFailed {
+				h.logger.Error("Bulk file metadata update failed",
+					zap.Uint("user_id", userID),
+					zap.Uint("file_id", outcome.FileID),
+					zap.Error(outcome.Err))
+			}
+		}
+		items[positions[i]] = item
+	}
+	for _, item := range items {
+		if item.Success {
+			result.Succeeded++
+		} else {
+			result.Failed++
+		}
+	}
+	result.Items, result.Changed = items, len(changedIDs)
+
+	if len(changedIDs) > 0 {
+		h.recordSecurity(c, h.logger, &audit.Event{
+			UserID:       userID,
+			Action:       audit.SecurityActionFileMetadataBulk,
+			ResourceType: audit.ResourceFile,
+			ResourceName: strconv.Itoa(len(changedIDs)) + "个文件",
+			Details:      bulkMetadataDetails(&req, changedIDs, result),
+		})
+	}
+	utils.Success(c, result)
+}
+
+// bulkMetadataDetails 汇总批量修改的内容，作为一条操作日志的附加信息
+func bulkMetadataDetails(req *BulkFileMetadataRequest, changedIDs []uint, result *BulkFileMetadataResult) map[string]interface{} {
+	details := map[string]interface{}{
+		"file_ids":  changedIDs,
+		"total":     result.Total,
+		"succeeded": result.Succeeded,
+		"failed":    result.Failed,
+	}
+	if len(req.AddTags) > 0 {
+		details["add_tags"] = req.AddTags
+	}
+	if len(req.RemoveTags) > 0 {
+		details["remove_tags"] = req.RemoveTags
+	}
+	var set, removed []string
+	for key, value := range req.Properties {
+		if value == nil {
+			removed = append(removed, key)
+		} else {
+			set = append(set, key)
+		}
+	}
+	if len(set) > 0 {
+		sort.Strings(set)
+		details["set_properties"] = set
+	}
+	if len(removed) > 0 {
+		sort.Strings(removed)
+		details["remove_properties"] = removed
+	}
+	return details
+}
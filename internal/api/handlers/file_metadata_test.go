@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/service/audit"
+	filesvc "cloudpan/internal/service/file"
+)
+
+// stubFileMetadataService 记录请求，文件ID在errs中时返回对应错误，否则返回修改成功
+type stubFileMetadataService struct {
+	targets []filesvc.MetadataTarget
+	req     *filesvc.BulkMetadataRequest
+	errs    map[uint]error
+}
+
+func (s *stubFileMetadataService) BulkUpdate(_ context.Context, targets []filesvc.MetadataTarget, req *filesvc.BulkMetadataRequest) ([]*filesvc.MetadataOutcome, error) {
+	if len(req.AddTags) == 0 && len(req.RemoveTags) == 0 && len(req.Properties) == 0 {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "请指定要修改的标签或属性")
+	}
+	s.targets, s.req = targets, req
+	outcomes := make([]*filesvc.MetadataOutcome, len(targets))
+	for i, target := range targets {
+		outcome := &filesvc.MetadataOutcome{FileID: target.FileID, Err: s.errs[target.FileID]}
+		if outcome.Err == nil {
+			outcome.Metadata = &filesvc.FileMetadata{FileID: target.FileID, Changed: target.FileID != 4}
+		}
+		outcomes[i] = outcome
+	}
+	return outcomes, nil
+}
+
+// denyingAuthorizer 文件ID在denied中时拒绝访问，在owners中时返回文件所有者
+type denyingAuthorizer struct {
+	owners map[uint]uint
+	denied map[uint]bool
+}
+
+func (a *denyingAuthorizer) ResolveActingUser(_ context.Context, userID, fileID uint, _ string) (uint, error) {
+	if a.denied[fileID] {
+		return 0, pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, "无权访问该文件")
+	}
+	if owner, ok := a.owners[fileID]; ok {
+		return owner, nil
+	}
+	return userID, nil
+}
+
+func setupFileMetadataRouter(service filesvc.MetadataService, securityAudit audit.SecurityAuditService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	handler := NewFileMetadataHandler(service, zap.NewNop())
+	handler.SetFileAuthorizer(&denyingAuthorizer{owners: map[uint]uint{3: 1}, denied: map[uint]bool{2: true}})
+	handler.SetSecurityAuditService(securityAudit)
+	router := gin.New()
+	authed := router.Group("", func(c *gin.Context) {
+		c.Set("user_id", uint64(7))
+	})
+	authed.POST("/files/metadata:bulk", handler.BulkUpdateMetadata)
+	return router
+}
+
+func postBulkMetadata(t *testing.T, router *gin.Engine, path, body string) (*httptest.ResponseRecorder, *BulkFileMetadataResult) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		return w, nil
+	}
+
+	var resp struct {
+		Data BulkFileMetadataResult `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return w, &resp.Data
+}
+
+func TestFileMetadataHandler_BulkUpdate(t *testing.T) {
+	service := &stubFileMetadataService{errs: map[uint]error{
+		5: pkgErrors.WrapErrorf(pkgErrors.ErrOperationNotAllowed, "单个文件最多添加%d个标签", filesvc.MaxTagsPerFile),
+		6: errors.New("connection reset"),
+	}}
+	securityAudit := &recordingSecurityAudit{}
+	router := setupFileMetadataRouter(service, securityAudit)
+
+	w, result := postBulkMetadata(t, router, "/files/metadata:bulk",
+		`{"ids":[1,2,3,1,4,5,6],"add_tags":["合同"],"remove_tags":["草稿"],"properties":{"项目":"alpha","状态":null}}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 6, result.Total)
+	assert.Equal(t, 3, result.Succeeded)
+	assert.Equal(t, 3, result.Failed)
+	assert.Equal(t, 2, result.Changed)
+
+	// 无权访问的文件不交给服务，授权访问的文件使用所有者身份
+	assert.Equal(t, []filesvc.MetadataTarget{{FileID: 1, UserID: 7}, {FileID: 3, UserID: 1}, {FileID: 4, UserID: 7},
+		{FileID: 5, UserID: 7}, {FileID: 6, UserID: 7}}, service.targets)
+	assert.Nil(t, service.req.Properties["状态"])
+	assert.Equal(t, "alpha", *service.req.Properties["项目"])
+
+	require.Len(t, result.Items, 6)
+	assert.Equal(t, uint(2), result.Items[1].ID, "结果顺序与请求一致")
+	assert.Equal(t, BatchReasonForbidden, result.Items[1].Reason)
+	assert.True(t, result.Items[2].Success)
+	assert.Equal(t, BatchReasonForbidden, result.Items[4].Reason)
+	assert.Equal(t, BatchReasonFailed, result.Items[5].Reason)
+	assert.Equal(t, "修改文件元数据失败", result.Items[5].Message)
+
+	// 多个文件只记录一条操作日志，不记录属性值
+	require.Len(t, securityAudit.events, 1)
+	event := securityAudit.events[0]
+	assert.Equal(t, audit.SecurityActionFileMetadataBulk, event.Action)
+	assert.Equal(t, []uint{1, 3}, event.Details["file_ids"])
+	assert.Equal(t, []string{"项目"}, event.Details["set_properties"])
+	assert.Equal(t, []string{"状态"}, event.Details["remove_properties"])
+	assert.NotContains(t, event.Details, "properties")
+
+	// 没有实际修改时不记录
+	_, result = postBulkMetadata(t, router, "/files/metadata:bulk", `{"ids":[4],"add_tags":["合同"]}`)
+	assert.Equal(t, 0, result.Changed)
+	assert.Len(t, securityAudit.events, 1)
+}
+
+func TestFileMetadataHandler_InvalidRequests(t *testing.T) {
+	router := setupFileMetadataRouter(&stubFileMetadataService{}, nil)
+
+	w, _ := postBulkMetadata(t, router, "/files/metadataX", `{"ids":[1],"add_tags":["a"]}`)
+	assert.Equal(t, utils.CodeNotFound, decodeShareResponse(t, w).Code)
+
+	ids := make([]string, filesvc.MaxBulkMetadataFiles+1)
+	for i := range ids {
+		ids[i] = strconv.Itoa(i + 1)
+	}
+	bodies := []string{
+		`{"ids":[]}`,
+		`{"ids":[0],"add_tags":["a"]}`,
+		`{"ids":[` + strings.Join(ids, ",") + `],"add_tags":["a"]}`,
+		`not json`,
+	}
+	for _, body := range bodies {
+		w, _ := postBulkMetadata(t, router, "/files/metadata:bulk", body)
+		assert.Equal(t, utils.CodeBadRequest, decodeShareResponse(t, w).Code, body)
+	}
+
+	// 没有要修改的内容时由服务返回验证错误
+	w, _ = postBulkMetadata(t, router, "/files/metadata:bulk", `{"ids":[1]}`)
+	assert.Equal(t, utils.CodeValidationError, decodeShareResponse(t, w).Code)
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+	"cloudpan/internal/service/file"
+)
+
+const (
+	// previewMaxAge 预览图允许浏览器缓存的时间
+	//
+	// 预览图按文件内容版本生成，内容更新后ETag变化，过期后通过If-None-Match重新验证
+	previewMaxAge = time.Hour
+	// previewRetryAfter 预览生成中时建议客户端重试的间隔(秒)
+	previewRetryAfter = 2
+)
+
+// FilePreviewHandler 文件预览处理器
+type FilePreviewHandler struct {
+	fileAccess
+	previewService file.PreviewService
+	logger         *zap.Logger
+}
+
+// NewFilePreviewHandler 创建文件预览处理器
+func NewFilePreviewHandler(previewService file.PreviewService, logger *zap.Logger) *FilePreviewHandler {
+	return &FilePreviewHandler{
+		previewService: previewService,
+		logger:         logger,
+	}
+}
+
+// GetPreview 获取文件缩略图或预览图
+//
+// @Summary 获取文件预览
+// @Description 返回图片(JPEG/PNG/GIF)或PDF首页的JPEG预览，size=thumbnail为缩略图(最长边默认256像素)，默认为预览图(最长边默认1024像素)。
+// @Description 预览在上传完成后由后台任务生成，尚未生成时返回202和Retry-After，客户端稍后重试；响应可被浏览器缓存1小时，携带 If-None-Match 且内容未变化时返回304。
+// @Description 访问权限与下载相同
+// @Tags 文件
+// @Produce jpeg
+// @Security BearerAuth
+// @Param id path int true "文件ID"
+// @Param size query string false "预览尺寸" Enums(thumbnail, preview) default(preview)
+// @Param If-None-Match header string false "上次响应的ETag"
+// @Success 200 {file} file "预览图"
+// @Success 202 {object} utils.Response "预览生成中"
+// @Success 304 "内容未变化"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "无权查看、文件已归档或存储不可用"
+// @Failure 404 {object} utils.Response "文件不存在或没有预览"
+// @Failure 500 {object} utils.Response "内部服务器错误"
+// @Router /api/v1/files/{id}/preview [get]
+func (h *FilePreviewHandler) GetPreview(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	fileID, ok := parseIDParam(c, "id")
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "文件ID格式错误")
+		return
+	}
+
+	kind := c.DefaultQuery("size", file.PreviewKindPreview)
+	actingUserID, ok := h.actingUser(c, userID, fileID, models.FilePermissionRead)
+	if !ok {
+		return
+	}
+
+	preview, err := h.previewService.Open(c.Request.Context(), actingUserID, fileID, kind)
+	if err != nil {
+		if errors.Is(err, file.ErrPreviewPending) {
+			c.Header("Retry-After", fmt.Sprint(previewRetryAfter))
+			c.Header("Cache-Control", "no-store")
+			utils.ErrorWithMessage(c, utils.CodePreviewPending, "预览生成中，请稍后重试")
+			return
+		}
+		h.logger.Warn("Failed to open file preview",
+			zap.Uint("user_id", userID),
+			zap.Uint("file_id", fileID),
+			zap.String("size", kind),
+			zap.Error(err))
+		respondServiceError(c, err, "获取预览失败")
+		return
+	}
+	defer preview.Content.Close()
+
+	// 预览按用户权限返回，只允许浏览器缓存，不允许共享缓存
+	c.Header("Cache-Control", fmt.Sprintf("private, max-age=%d", int(previewMaxAge/time.Second)))
+	c.Header("ETag", preview.ETag)
+	c.Header("Last-Modified", preview.ModTime.UTC().Format(http.TimeFormat))
+	c.Header("X-Content-Type-Options", "nosniff")
+	if ifNoneMatch(c.GetHeader("If-None-Match"), preview.ETag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.DataFromReader(http.StatusOK, preview.Size, preview.MimeType, preview.Content, nil)
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+	"cloudpan/internal/service/file"
+	"cloudpan/internal/service/jobs"
+)
+
+// MockFilePreviewService 模拟文件预览服务
+type MockFilePreviewService struct {
+	mock.Mock
+}
+
+func (m *MockFilePreviewService) Schedule(ctx context.Context, f *models.File, priority jobs.Priority) {
+	m.Called(ctx, f, priority)
+}
+
+func (m *MockFilePreviewService) Generate(ctx context.Context, fileID uint) error {
+	args := m.Called(ctx, fileID)
+	return args.Error(0)
+}
+
+func (m *MockFilePreviewService) Open(ctx context.Context, userID, fileID uint, kind string) (*file.FilePreview, error) {
+	args := m.Called(ctx, userID, fileID, kind)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*file.FilePreview), args.Error(1)
+}
+
+func setupFilePreviewRouter(service *MockFilePreviewService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler := NewFilePreviewHandler(service, zap.NewNop())
+	router.GET("/files/:id/preview", func(c *gin.Context) {
+		c.Set("user_id", uint64(7))
+	}, handler.GetPreview)
+	return router
+}
+
+func newTestFilePreview(kind string) *file.FilePreview {
+	return &file.FilePreview{
+		FileID:   5,
+		Kind:     kind,
+		MimeType: "image/jpeg",
+		Size:     4,
+		ModTime:  time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		ETag:     `"abc-` + kind + `"`,
+		Content:  io.NopCloser(strings.NewReader("jpeg")),
+	}
+}
+
+func TestFilePreviewHandler_GetPreview(t *testing.T) {
+	t.Run("serves preview by default", func(t *testing.T) {
+		service := new(MockFilePreviewService)
+		service.On("Open", mock.Anything, uint(7), uint(5), file.PreviewKindPreview).Return(newTestFilePreview(file.PreviewKindPreview), nil)
+
+		w := httptest.NewRecorder()
+		setupFilePreviewRouter(service).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/files/5/preview", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "jpeg", w.Body.String())
+		assert.Equal(t, "image/jpeg", w.Header().Get("Content-Type"))
+		assert.Equal(t, "private, max-age=3600", w.Header().Get("Cache-Control"))
+		assert.Equal(t, `"abc-preview"`, w.Header().Get("ETag"))
+		assert.Equal(t, "Fri, 01 Mar 2024 00:00:00 GMT", w.Header().Get("Last-Modified"))
+		service.AssertExpectations(t)
+	})
+
+	t.Run("thumbnail size", func(t *testing.T) {
+		service := new(MockFilePreviewService)
+		service.On("Open", mock.Anything, uint(7), uint(5), file.PreviewKindThumbnail).Return(newTestFilePreview(file.PreviewKindThumbnail), nil)
+
+		w := httptest.NewRecorder()
+		setupFilePreviewRouter(service).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/files/5/preview?size=thumbnail", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, `"abc-thumbnail"`, w.Header().Get("ETag"))
+	})
+
+	t.Run("not modified", func(t *testing.T) {
+		service := new(MockFilePreviewService)
+		service.On("Open", mock.Anything, uint(7), uint(5), file.PreviewKindPreview).Return(newTestFilePreview(file.PreviewKindPreview), nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/files/5/preview", nil)
+		req.Header.Set("If-None-Match", `W/"abc-preview"`)
+		w := httptest.NewRecorder()
+		setupFilePreviewRouter(service).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotModified, w.Code)
+		assert.Empty(t, w.Body.String())
+		assert.Equal(t, `"abc-preview"`, w.Header().Get("ETag"))
+	})
+
+	t.Run("pending preview", func(t *testing.T) {
+		service := new(MockFilePreviewService)
+		service.On("Open", mock.Anything, uint(7), uint(5), file.PreviewKindPreview).Return(nil, file.ErrPreviewPending)
+
+		w := httptest.NewRecorder()
+		setupFilePreviewRouter(service).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/files/5/preview", nil))
+
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		assert.Equal(t, "2", w.Header().Get("Retry-After"))
+		assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+		assert.Contains(t, w.Body.String(), `"code":1028`)
+	})
+
+	t.Run("no preview", func(t *testing.T) {
+		service := new(MockFilePreviewService)
+		service.On("Open", mock.Anything, uint(7), uint(5), file.PreviewKindPreview).
+			Return(nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "该文件没有预览"))
+
+		w := httptest.NewRecorder()
+		setupFilePreviewRouter(service).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/files/5/preview", nil))
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("invalid id", func(t *testing.T) {
+		service := new(MockFilePreviewService)
+
+		w := httptest.NewRecorder()
+		setupFilePreviewRouter(service).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/files/abc/preview", nil))
+
+		assert.Equal(t, utils.CodeBadRequest.GetHTTPStatus(), w.Code)
+		service.AssertNotCalled(t, "Open", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/service/file"
+)
+
+// FileSearchHandler 文件搜索处理器
+type FileSearchHandler struct {
+	service file.SearchService
+	logger  *zap.Logger
+}
+
+// NewFileSearchHandler 创建文件搜索处理器
+func NewFileSearchHandler(service file.SearchService, logger *zap.Logger) *FileSearchHandler {
+	return &FileSearchHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// SearchFiles 搜索文件
+//
+// @Summary 搜索文件
+// @Description 按关键词搜索当前用户的文件和文件夹，关键词匹配文件名、标签和描述，结果按相关度排序。
+// @Description 可按标签(需全部包含)、扩展名、MIME类型(以/结尾时按前缀匹配，如image/)、大小范围和日期范围筛选，多个值用逗号分隔。
+// @Description 至少需要关键词或一个筛选条件；结果页缓存15分钟，期间被删除的文件不会返回
+// @Tags 文件
+// @Produce json
+// @Security BearerAuth
+// @Param q query string false "关键词(最多100个字符)"
+// @Param tags query string false "标签，逗号分隔" example(工作,合同)
+// @Param ext query string false "扩展名，逗号分隔" example(pdf,docx)
+// @Param mime query string false "MIME类型，逗号分隔" example(image/,application/pdf)
+// @Param min_size query int false "最小文件大小(字节)"
+// @Param max_size query int false "最大文件大小(字节)"
+// @Param date_field query string false "日期范围作用的字段" Enums(created, updated) default(updated)
+// @Param from query string false "开始时间(含)，RFC3339或YYYY-MM-DD" example(2024-01-01)
+// @Param to query string false "结束时间(不含)，RFC3339或YYYY-MM-DD" example(2024-02-01)
+// @Param page query int false "页码" default(1)
+// @Param page_size query int false "每页数量(最大200)" default(50)
+// @Success 200 {object} utils.ListResponse{data=[]models.File} "搜索结果"
+// @Failure 400 {object} utils.Response "请求参数错误或缺少搜索条件"
+// @Failure 401 {object} utils.Response "未认证"
+// @Router /api/v1/files/search [get]
+func (h *FileSearchHandler) SearchFiles(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	req := file.SearchRequest{
+		Keyword:    c.Query("q"),
+		Tags:       splitQueryList(c.Query("tags")),
+		Extensions: splitQueryList(c.Query("ext")),
+		MimeTypes:  splitQueryList(c.Query("mime")),
+		DateField:  c.Query("date_field"),
+	}
+
+	var err error
+	if req.MinSize, err = parseOptionalInt64(c.Query("min_size")); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "最小文件大小格式错误")
+		return
+	}
+	if req.MaxSize, err = parseOptionalInt64(c.Query("max_size")); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "最大文件大小格式错误")
+		return
+	}
+	if req.From, err = parseSearchTime(c.Query("from")); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "开始时间格式错误")
+		return
+	}
+	if req.To, err = parseSearchTime(c.Query("to")); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "结束时间格式错误")
+		return
+	}
+
+	req.Page, _ = strconv.Atoi(c.Query("page"))
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	req.PageSize, _ = strconv.Atoi(c.Query("page_size"))
+	if req.PageSize < 1 {
+		req.PageSize = defaultFilePageSize
+	}
+	if req.PageSize > maxFilePageSize {
+		req.PageSize = maxFilePageSize
+	}
+
+	result, err := h.service.Search(c.Request.Context(), userID, req)
+	if err != nil {
+		respondServiceError(c, err, "搜索文件失败")
+		return
+	}
+
+	utils.SuccessList(c, result.Files, utils.NewPagination(req.Page, req.PageSize, result.Total))
+}
+
+// GetSearchHistory 获取搜索历史
+//
+// @Summary 获取搜索历史
+// @Description 返回当前用户最近搜索的关键词(最多20条)，按搜索时间倒序，24小时未搜索时自动清空
+// @Tags 文件
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response{data=[]cache.SearchHistoryEntry} "搜索历史"
+// @Failure 401 {object} utils.Response "未认证"
+// @Router /api/v1/files/search/history [get]
+func (h *FileSearchHandler) GetSearchHistory(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	entries, err := h.service.History(c.Request.Context(), userID)
+	if err != nil {
+		h.logger.Error("Failed to get search history", zap.Uint("user_id", userID), zap.Error(err))
+		utils.InternalErrorWithMessage(c, "获取搜索历史失败")
+		return
+	}
+	utils.Success(c, entries)
+}
+
+// ClearSearchHistory 清空搜索历史
+//
+// @Summary 清空搜索历史
+// @Description 清空当前用户的全部搜索历史
+// @Tags 文件
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response "已清空"
+// @Failure 401 {object} utils.Response "未认证"
+// @Router /api/v1/files/search/history [delete]
+func (h *FileSearchHandler) ClearSearchHistory(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	if err := h.service.ClearHistory(c.Request.Context(), userID); err != nil {
+		h.logger.Error("Failed to clear search history", zap.Uint("user_id", userID), zap.Error(err))
+		utils.InternalErrorWithMessage(c, "清空搜索历史失败")
+		return
+	}
+	utils.SuccessWithMessage(c, "搜索历史已清空", nil)
+}
+
+// splitQueryList 拆分逗号分隔的查询参数，去掉空值
+func splitQueryList(value string) []string {
+	if value == "" {
+		return nil
+	}
+	var values []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			values = append(values, item)
+		}
+	}
+	return values
+}
+
+// parseOptionalInt64 解析可选的整数参数，为空时返回nil
+func parseOptionalInt64(value string) (*int64, error) {
+	if value == "" {
+		return nil, nil
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+// parseSearchTime 解析RFC3339或YYYY-MM-DD(UTC零点)格式的时间，为空时返回nil
+func parseSearchTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		if t, err = time.Parse(time.DateOnly, value); err != nil {
+			return nil, err
+		}
+	}
+	return &t, nil
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/cache"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/repository/models"
+	"cloudpan/internal/service/file"
+)
+
+// stubSearchService 记录搜索条件的文件搜索服务
+type stubSearchService struct {
+	req     file.SearchRequest
+	history []cache.SearchHistoryEntry
+}
+
+func (s *stubSearchService) Search(_ context.Context, _ uint, req file.SearchRequest) (*file.SearchResult, error) {
+	s.req = req
+	if req.Keyword == "" && len(req.Extensions) == 0 {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "请输入搜索关键词或筛选条件")
+	}
+	f := &models.File{Name: "季度报告.pdf"}
+	f.ID = 3
+	return &file.SearchResult{Files: []*models.File{f}, Total: 1}, nil
+}
+
+func (s *stubSearchService) History(context.Context, uint) ([]cache.SearchHistoryEntry, error) {
+	return s.history, nil
+}
+
+func (s *stubSearchService) ClearHistory(context.Context, uint) error {
+	s.history = nil
+	return nil
+}
+
+func TestFileSearchHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	service := &stubSearchService{history: []cache.SearchHistoryEntry{{Query: "报告"}}}
+	handler := NewFileSearchHandler(service, zap.NewNop())
+
+	router := gin.New()
+	authed := router.Group("/files", func(c *gin.Context) { c.Set("user_id", uint64(1)) })
+	authed.GET("/search", handler.SearchFiles)
+	authed.GET("/search/history", handler.GetSearchHistory)
+	authed.DELETE("/search/history", handler.ClearSearchHistory)
+
+	serve := func(method, target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+		return w
+	}
+
+	t.Run("解析筛选条件", func(t *testing.T) {
+		w := serve(http.MethodGet, "/files/search?q=%E6%8A%A5%E5%91%8A&tags=%E5%B7%A5%E4%BD%9C,,%20%E5%90%88%E5%90%8C&ext=pdf,docx&mime=image/&min_size=1024&date_field=created&from=2024-01-01&to=2024-02-01T00:00:00Z&page=2&page_size=500")
+		require.Equal(t, http.StatusOK, w.Code)
+		req := service.req
+		assert.Equal(t, "报告", req.Keyword)
+		assert.Equal(t, []string{"工作", "合同"}, req.Tags)
+		assert.Equal(t, []string{"pdf", "docx"}, req.Extensions)
+		assert.Equal(t, []string{"image/"}, req.MimeTypes)
+		require.NotNil(t, req.MinSize)
+		assert.Equal(t, int64(1024), *req.MinSize)
+		assert.Nil(t, req.MaxSize)
+		assert.Equal(t, "created", req.DateField)
+		assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), *req.From)
+		assert.Equal(t, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), *req.To)
+		assert.Equal(t, 2, req.Page)
+		assert.Equal(t, maxFilePageSize, req.PageSize)
+	})
+
+	t.Run("参数错误", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, serve(http.MethodGet, "/files/search?q=a&min_size=abc").Code)
+		assert.Equal(t, http.StatusBadRequest, serve(http.MethodGet, "/files/search?q=a&from=yesterday").Code)
+		assert.Equal(t, http.StatusBadRequest, serve(http.MethodGet, "/files/search").Code)
+	})
+
+	t.Run("搜索历史", func(t *testing.T) {
+		w := serve(http.MethodGet, "/files/search/history")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "报告")
+
+		assert.Equal(t, http.StatusOK, serve(http.MethodDelete, "/files/search/history").Code)
+		assert.Empty(t, service.history)
+	})
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+	"cloudpan/internal/service/file"
+)
+
+// FileTagHandler 文件标签处理器
+type FileTagHandler struct {
+	fileAccess
+	service file.TagService
+	logger  *zap.Logger
+}
+
+// NewFileTagHandler 创建文件标签处理器
+func NewFileTagHandler(service file.TagService, logger *zap.Logger) *FileTagHandler {
+	return &FileTagHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// AddFileTagRequest 添加文件标签请求
+type AddFileTagRequest struct {
+	Tag   string `json:"tag" binding:"required"` // 标签名称，最多40个字符，不能包含逗号
+	Color string `json:"color"`                  // 标签颜色，如#1890ff
+}
+
+// ListUserTags 列出当前用户的标签
+//
+// @Summary 列出我的标签
+// @Description 返回当前用户添加过的全部标签及每个标签下的可用文件和文件夹数，按文件数降序排列
+// @Tags 文件
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response{data=[]filerepo.TagCount} "标签列表"
+// @Failure 401 {object} utils.Response "未认证"
+// @Router /api/v1/files/tags [get]
+func (h *FileTagHandler) ListUserTags(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	tags, err := h.service.ListUserTags(c.Request.Context(), userID)
+	if err != nil {
+		respondServiceError(c, err, "获取标签列表失败")
+		return
+	}
+	utils.Success(c, tags)
+}
+
+// ListFileTags 列出文件上的标签
+//
+// @Summary 列出文件标签
+// @Description 返回文件上的标签，按添加顺序排列。获得read授权的用户可以查看他人文件的标签(文件所有者添加的标签)
+// @Tags 文件
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "文件ID"
+// @Success 200 {object} utils.Response{data=[]file.FileTag} "标签列表"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "无权访问该文件或文件当前不可操作"
+// @Failure 404 {object} utils.Response "文件不存在"
+// @Router /api/v1/files/{id}/tags [get]
+func (h *FileTagHandler) ListFileTags(c *gin.Context) {
+	userID, fileID, ok := h.fileParams(c, models.FilePermissionRead)
+	if !ok {
+		return
+	}
+
+	tags, err := h.service.ListFileTags(c.Request.Context(), userID, fileID)
+	if err != nil {
+		respondServiceError(c, err, "获取文件标签失败")
+		return
+	}
+	utils.Success(c, tags)
+}
+
+// AddFileTag 为文件添加标签
+//
+// @Summary 添加文件标签
+// @Description 为文件或文件夹添加标签，同一文件上的标签不区分大小写唯一，单个文件最多20个标签。
+// @Description 获得write授权的用户可以为他人的文件添加标签，标签属于文件所有者
+// @Tags 文件
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "文件ID"
+// @Param request body AddFileTagRequest true "标签"
+// @Success 200 {object} utils.Response{data=file.FileTag} "添加成功"
+// @Failure 400 {object} utils.Response "请求参数错误或标签格式错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "无权修改该文件、文件当前不可操作或标签数已达上限"
+// @Failure 404 {object} utils.Response "文件不存在"
+// @Failure 409 {object} utils.Response "文件已有该标签"
+// @Router /api/v1/files/{id}/tags [post]
+func (h *FileTagHandler) AddFileTag(c *gin.Context) {
+	var req AddFileTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+		return
+	}
+	userID, fileID, ok := h.fileParams(c, models.FilePermissionWrite)
+	if !ok {
+		return
+	}
+
+	tag, err := h.service.AddTag(c.Request.Context(), userID, fileID, &file.AddTagRequest{Tag: req.Tag, Color: req.Color})
+	if err != nil {
+		respondServiceError(c, err, "添加文件标签失败")
+		return
+	}
+	utils.Success(c, tag)
+}
+
+// RemoveFileTag 删除文件上的标签
+//
+// @Summary 删除文件标签
+// @Description 删除文件上的标签，标签名称不区分大小写。获得write授权的用户可以删除他人文件上的标签
+// @Tags 文件
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "文件ID"
+// @Param tag path string true "标签名称"
+// @Success 200 {object} utils.Response "删除成功"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "无权修改该文件或文件当前不可操作"
+// @Failure 404 {object} utils.Response "文件不存在或文件没有该标签"
+// @Router /api/v1/files/{id}/tags/{tag} [delete]
+func (h *FileTagHandler) RemoveFileTag(c *gin.Context) {
+	userID, fileID, ok := h.fileParams(c, models.FilePermissionWrite)
+	if !ok {
+		return
+	}
+
+	// 标签通过通配参数传入，可以包含斜杠
+	tag := strings.TrimPrefix(c.Param("tag"), "/")
+	if err := h.service.RemoveTag(c.Request.Context(), userID, fileID, tag); err != nil {
+		respondServiceError(c, err, "删除文件标签失败")
+		return
+	}
+	utils.SuccessWithMessage(c, "标签已删除", nil)
+}
+
+// fileParams 解析文件ID并检查权限，返回调用服务时使用的用户ID，失败时已写入响应
+func (h *FileTagHandler) fileParams(c *gin.Context, permission string) (uint, uint, bool) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return 0, 0, false
+	}
+	fileID, ok := parseIDParam(c, "id")
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "文件ID格式错误")
+		return 0, 0, false
+	}
+	actingUserID, ok := h.actingUser(c, userID, fileID, permission)
+	if !ok {
+		return 0, 0, false
+	}
+	return actingUserID, fileID, true
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/utils"
+	filerepo "cloudpan/internal/repository/file"
+	"cloudpan/internal/repository/models"
+	"cloudpan/internal/service/file"
+)
+
+// MockTagService 模拟文件标签服务
+type MockTagService struct {
+	mock.Mock
+}
+
+func (m *MockTagService) ListFileTags(ctx context.Context, userID, fileID uint) ([]*file.FileTag, error) {
+	args := m.Called(ctx, userID, fileID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*file.FileTag), args.Error(1)
+}
+
+func (m *MockTagService) AddTag(ctx context.Context, userID, fileID uint, req *file.AddTagRequest) (*file.FileTag, error) {
+	args := m.Called(ctx, userID, fileID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*file.FileTag), args.Error(1)
+}
+
+func (m *MockTagService) RemoveTag(ctx context.Context, userID, fileID uint, tag string) error {
+	args := m.Called(ctx, userID, fileID, tag)
+	return args.Error(0)
+}
+
+func (m *MockTagService) ListUserTags(ctx context.Context, userID uint) ([]filerepo.TagCount, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]filerepo.TagCount), args.Error(1)
+}
+
+func setupFileTagRouter(service *MockTagService, authorizer FileAuthorizer) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler := NewFileTagHandler(service, zap.NewNop())
+	if authorizer != nil {
+		handler.SetFileAuthorizer(authorizer)
+	}
+	authed := router.Group("", func(c *gin.Context) {
+		c.Set("user_id", uint64(7))
+	})
+	authed.GET("/files/tags", handler.ListUserTags)
+	authed.GET("/files/:id/tags", handler.ListFileTags)
+	authed.POST("/files/:id/tags", handler.AddFileTag)
+	authed.DELETE("/files/:id/tags/*tag", handler.RemoveFileTag)
+	return router
+}
+
+func TestFileTagHandler_ListUserTags(t *testing.T) {
+	service := new(MockTagService)
+	service.On("ListUserTags", mock.Anything, uint(7)).Return([]filerepo.TagCount{{Tag: "合同", Count: 3}}, nil)
+
+	w := httptest.NewRecorder()
+	setupFileTagRouter(service, nil).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/files/tags", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `{"tag":"合同","count":3}`)
+	service.AssertExpectations(t)
+}
+
+func TestFileTagHandler_AddAndList(t *testing.T) {
+	service := new(MockTagService)
+	service.On("AddTag", mock.Anything, uint(7), uint(5), &file.AddTagRequest{Tag: "合同", Color: "#1890ff"}).
+		Return(&file.FileTag{Tag: "合同"}, nil)
+	service.On("ListFileTags", mock.Anything, uint(7), uint(5)).Return([]*file.FileTag{{Tag: "合同"}}, nil)
+	router := setupFileTagRouter(service, nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/files/5/tags", strings.NewReader(`{"tag":"合同","color":"#1890ff"}`)))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/files/5/tags", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"tag":"合同"`)
+	service.AssertExpectations(t)
+}
+
+func TestFileTagHandler_AddTagErrors(t *testing.T) {
+	service := new(MockTagService)
+	service.On("AddTag", mock.Anything, uint(7), uint(5), mock.Anything).
+		Return(nil, pkgErrors.WrapError(pkgErrors.ErrResourceExists, "文件已有该标签"))
+	router := setupFileTagRouter(service, nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/files/5/tags", strings.NewReader(`{"tag":"合同"}`)))
+	assert.Equal(t, utils.CodeConflict, decodeShareResponse(t, w).Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/files/5/tags", strings.NewReader(`{}`)))
+	assert.Equal(t, utils.CodeBadRequest, decodeShareResponse(t, w).Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/files/x/tags", strings.NewReader(`{"tag":"合同"}`)))
+	assert.Equal(t, utils.CodeBadRequest, decodeShareResponse(t, w).Code)
+}
+
+func TestFileTagHandler_RemoveTag(t *testing.T) {
+	service := new(MockTagService)
+	service.On("RemoveTag", mock.Anything, uint(7), uint(5), "项目/2024").Return(nil)
+	service.On("RemoveTag", mock.Anything, uint(7), uint(5), "missing").
+		Return(pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "文件没有该标签"))
+	router := setupFileTagRouter(service, nil)
+
+	// 标签可以包含斜杠
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/files/5/tags/项目/2024", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/files/5/tags/missing", nil))
+	assert.Equal(t, utils.CodeNotFound, decodeShareResponse(t, w).Code)
+	service.AssertExpectations(t)
+}
+
+func TestFileTagHandler_Grants(t *testing.T) {
+	// 用户7对用户3的文件5只有read权限
+	authorizer := &stubFileAuthorizer{owners: map[uint]map[string]uint{
+		5: {models.FilePermissionRead: 3},
+	}}
+	service := new(MockTagService)
+	service.On("ListFileTags", mock.Anything, uint(3), uint(5)).Return([]*file.FileTag{}, nil)
+	service.On("AddTag", mock.Anything, uint(7), uint(5), mock.Anything).
+		Return(nil, pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, "无权访问该文件"))
+	router := setupFileTagRouter(service, authorizer)
+
+	// 获得read授权的用户以文件所有者身份查看标签
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/files/5/tags", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// 没有write授权时以自身身份添加，不是文件所有者
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/files/5/tags", strings.NewReader(`{"tag":"合同"}`)))
+	assert.Equal(t, utils.CodeForbidden, decodeShareResponse(t, w).Code)
+	service.AssertExpectations(t)
+}
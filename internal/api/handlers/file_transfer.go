@@ -0,0 +1,315 @@
+package handlers
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+	"cloudpan/internal/service/audit"
+	"cloudpan/internal/service/file"
+)
+
+// 转移记录分页默认值
+const (
+	defaultTransferPageSize = 20
+	maxTransferPageSize     = 100
+)
+
+// FileTransferHandler 文件所有权转移处理器
+type FileTransferHandler struct {
+	securityAudit
+	service file.TransferService
+	logger  *zap.Logger
+}
+
+// NewFileTransferHandler 创建文件所有权转移处理器
+func NewFileTransferHandler(service file.TransferService, logger *zap.Logger) *FileTransferHandler {
+	return &FileTransferHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// InitiateTransfer 发起所有权转移
+//
+// @Summary 发起文件所有权转移
+// @Description 把自己的文件或文件夹(连同全部子项)转给另一个用户，接收方需在有效期内(默认7天)接受。
+// @Description share_mode 为 keep(默认)时已有分享保持有效并改由接收方分享，为 revoke 时停用有效的分享。
+// @Description 转移进行中的文件及其上级文件夹和子项不能再次发起转移；文件标签不随文件转移
+// @Tags 文件
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body file.TransferRequest true "转移的文件和接收方"
+// @Success 200 {object} utils.Response{data=models.FileTransfer} "发起成功"
+// @Failure 400 {object} utils.Response "请求参数错误或不能转给自己"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "只能转移自己的文件、接收方账户不可用或文件当前不可转移"
+// @Failure 404 {object} utils.Response "文件或接收方不存在"
+// @Failure 409 {object} utils.Response "文件或其所在文件夹正在转移中"
+// @Router /api/v1/transfers [post]
+func (h *FileTransferHandler) InitiateTransfer(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	var req file.TransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+		return
+	}
+
+	transfer, err := h.service.Initiate(c.Request.Context(), userID, &req)
+	if err != nil {
+		respondServiceError(c, err, "发起转移失败")
+		return
+	}
+
+	h.logger.Info("File transfer initiated",
+		zap.Uint("user_id", userID),
+		zap.Uint("transfer_id", transfer.ID),
+		zap.Uint("file_id", transfer.FileID),
+		zap.Uint("to_user_id", transfer.ToUserID),
+		zap.String("ip", c.ClientIP()))
+	h.recordSecurity(c, h.logger, &audit.Event{
+		Action:       audit.SecurityActionTransferStart,
+		ResourceType: audit.ResourceFile,
+		ResourceID:   strconv.FormatUint(uint64(transfer.FileID), 10),
+		ResourceName: transfer.FileName,
+		After:        fileTransferSnapshot(transfer),
+	})
+	utils.Success(c, transfer)
+}
+
+// ListTransfers 分页查询转移记录
+//
+// @Summary 查询文件所有权转移记录
+// @Description 按创建时间倒序返回自己发起的和转给自己的转移，可按方向和状态筛选
+// @Tags 文件
+// @Produce json
+// @Security BearerAuth
+// @Param direction query string false "方向：incoming(转给自己的)/outgoing(自己发起的)，默认全部"
+// @Param status query string false "状态：pending/accepted/completed/declined/cancelled/expired，默认全部"
+// @Param page query int false "页码，默认1"
+// @Param page_size query int false "每页记录数，默认20，最大100"
+// @Success 200 {object} utils.Response{data=[]models.FileTransfer} "查询成功"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 500 {object} utils.Response "内部服务器错误"
+// @Router /api/v1/transfers [get]
+func (h *FileTransferHandler) ListTransfers(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	page, _ := strconv.Atoi(c.Query("page"))
+	if page < 1 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(c.Query("page_size"))
+	if pageSize < 1 {
+		pageSize = defaultTransferPageSize
+	}
+	pageSize = min(pageSize, maxTransferPageSize)
+
+	transfers, total, err := h.service.List(c.Request.Context(), userID, c.Query("direction"), c.Query("status"), page, pageSize)
+	if err != nil {
+		respondServiceError(c, err, "查询转移记录失败")
+		return
+	}
+	utils.SuccessList(c, transfers, utils.NewPagination(page, pageSize, total))
+}
+
+// GetTransfer 获取转移详情
+//
+// @Summary 获取文件所有权转移详情
+// @Description 只有发起方和接收方可以查看；完成失败时 error 为最近一次失败的原因
+// @Tags 文件
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "转移ID"
+// @Success 200 {object} utils.Response{data=models.FileTransfer} "获取成功"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 404 {object} utils.Response "转移不存在"
+// @Router /api/v1/transfers/{id} [get]
+func (h *FileTransferHandler) GetTransfer(c *gin.Context) {
+	userID, transferID, ok := h.transferParams(c)
+	if !ok {
+		return
+	}
+	transfer, err := h.service.Get(c.Request.Context(), userID, transferID)
+	if err != nil {
+		respondServiceError(c, err, "获取转移失败")
+		return
+	}
+	utils.Success(c, transfer)
+}
+
+// AcceptTransfer 接受转移
+//
+// @Summary 接受文件所有权转移
+// @Description 接收方接受后文件移到接收方的根目录(同名时自动重命名)，全部子项改到接收方名下，
+// @Description 存储用量(含历史版本)从发起方转到接收方。接收方存储空间不足等原因导致完成失败时，
+// @Description 转移保持已接受状态并记录失败原因，处理后可以通过完成接口重试
+// @Tags 文件
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "转移ID"
+// @Success 200 {object} utils.Response{data=models.FileTransfer} "转移已完成"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "只有接收方可以接受、转移已结束或已过期、存储空间不足"
+// @Failure 404 {object} utils.Response "转移或文件不存在"
+// @Router /api/v1/transfers/{id}/accept [post]
+func (h *FileTransferHandler) AcceptTransfer(c *gin.Context) {
+	h.finish(c, h.service.Accept, "接受转移失败")
+}
+
+// CompleteTransfer 重试完成转移
+//
+// @Summary 重试完成文件所有权转移
+// @Description 重试完成已接受但完成失败的转移，发起方和接收方都可以重试
+// @Tags 文件
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "转移ID"
+// @Success 200 {object} utils.Response{data=models.FileTransfer} "转移已完成"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "转移不是已接受状态或存储空间不足"
+// @Failure 404 {object} utils.Response "转移或文件不存在"
+// @Router /api/v1/transfers/{id}/complete [post]
+func (h *FileTransferHandler) CompleteTransfer(c *gin.Context) {
+	h.finish(c, h.service.Complete, "完成转移失败")
+}
+
+// DeclineTransfer 拒绝转移
+//
+// @Summary 拒绝文件所有权转移
+// @Description 接收方拒绝等待接受的转移，文件保留在发起方名下
+// @Tags 文件
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "转移ID"
+// @Success 200 {object} utils.Response{data=models.FileTransfer} "已拒绝"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "只有接收方可以拒绝或转移已结束"
+// @Failure 404 {object} utils.Response "转移不存在"
+// @Router /api/v1/transfers/{id}/decline [post]
+func (h *FileTransferHandler) DeclineTransfer(c *gin.Context) {
+	userID, transferID, ok := h.transferParams(c)
+	if !ok {
+		return
+	}
+	transfer, err := h.service.Decline(c.Request.Context(), userID, transferID)
+	if err != nil {
+		respondServiceError(c, err, "拒绝转移失败")
+		return
+	}
+	utils.Success(c, transfer)
+}
+
+// CancelTransfer 取消转移
+//
+// @Summary 取消文件所有权转移
+// @Description 发起方取消尚未完成的转移，文件保留在发起方名下
+// @Tags 文件
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "转移ID"
+// @Success 200 {object} utils.Response{data=models.FileTransfer} "已取消"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "只有发起方可以取消或转移已结束"
+// @Failure 404 {object} utils.Response "转移不存在"
+// @Router /api/v1/transfers/{id}/cancel [post]
+func (h *FileTransferHandler) CancelTransfer(c *gin.Context) {
+	userID, transferID, ok := h.transferParams(c)
+	if !ok {
+		return
+	}
+	transfer, err := h.service.Cancel(c.Request.Context(), userID, transferID)
+	if err != nil {
+		respondServiceError(c, err, "取消转移失败")
+		return
+	}
+	utils.Success(c, transfer)
+}
+
+// finish 接受或重试完成转移，完成后记录所有者变更的安全审计
+func (h *FileTransferHandler) finish(c *gin.Context, complete func(ctx context.Context, userID, transferID uint) (*models.FileTransfer, error), failure string) {
+	userID, transferID, ok := h.transferParams(c)
+	if !ok {
+		return
+	}
+	transfer, err := complete(c.Request.Context(), userID, transferID)
+	if err != nil {
+		respondServiceError(c, err, failure)
+		return
+	}
+
+	h.logger.Info("File transfer completed",
+		zap.Uint("user_id", userID),
+		zap.Uint("transfer_id", transfer.ID),
+		zap.Uint("file_id", transfer.FileID),
+		zap.String("ip", c.ClientIP()))
+	h.recordSecurity(c, h.logger, fileOwnerChangeEvent(transfer))
+	utils.Success(c, transfer)
+}
+
+// transferParams 解析当前用户和转移ID，失败时已写入响应
+func (h *FileTransferHandler) transferParams(c *gin.Context) (uint, uint, bool) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return 0, 0, false
+	}
+	transferID, ok := parseIDParam(c, "id")
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "转移ID格式错误")
+		return 0, 0, false
+	}
+	return userID, transferID, true
+}
+
+// fileOwnerChangeEvent 生成转移完成时文件所有者变更的安全审计记录
+func fileOwnerChangeEvent(transfer *models.FileTransfer) *audit.Event {
+	return &audit.Event{
+		Action:       audit.SecurityActionOwnerChange,
+		ResourceType: audit.ResourceFile,
+		ResourceID:   strconv.FormatUint(uint64(transfer.FileID), 10),
+		ResourceName: transfer.FileName,
+		Before:       map[string]interface{}{"owner_id": transfer.FromUserID},
+		After:        fileTransferSnapshot(transfer),
+	}
+}
+
+// fileTransferSnapshot 转移的审计快照
+func fileTransferSnapshot(transfer *models.FileTransfer) map[string]interface{} {
+	snapshot := map[string]interface{}{
+		"transfer_id":  transfer.ID,
+		"from_user_id": transfer.FromUserID,
+		"to_user_id":   transfer.ToUserID,
+		"status":       transfer.Status,
+		"share_mode":   transfer.ShareMode,
+	}
+	if transfer.Status == models.TransferStatusCompleted {
+		snapshot["owner_id"] = transfer.ToUserID
+		snapshot["items"] = transfer.Items
+		snapshot["size"] = transfer.Size
+	}
+	if transfer.ForcedBy != nil {
+		snapshot["forced_by"] = *transfer.ForcedBy
+	}
+	return snapshot
+}
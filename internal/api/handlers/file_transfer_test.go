@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+	"cloudpan/internal/service/audit"
+	"cloudpan/internal/service/file"
+)
+
+// stubTransferService 记录调用参数的文件所有权转移服务
+type stubTransferService struct {
+	file.TransferService
+	initiated *file.TransferRequest
+	forced    *file.ForceTransferRequest
+	direction string
+	pageSize  int
+}
+
+func (s *stubTransferService) Initiate(_ context.Context, userID uint, req *file.TransferRequest) (*models.FileTransfer, error) {
+	s.initiated = req
+	if req.ToUserID == userID {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "不能把文件转移给自己")
+	}
+	return &models.FileTransfer{FileID: req.FileID, FileName: "docs", FromUserID: userID, ToUserID: req.ToUserID, Status: models.TransferStatusPending}, nil
+}
+
+func (s *stubTransferService) Accept(_ context.Context, userID, transferID uint) (*models.FileTransfer, error) {
+	if transferID != 3 {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "转移不存在")
+	}
+	if userID != 8 {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, "只有接收方可以接受转移")
+	}
+	return &models.FileTransfer{FileID: 1, FileName: "docs (1)", FromUserID: 7, ToUserID: 8, Status: models.TransferStatusCompleted, Items: 4, Size: 10}, nil
+}
+
+func (s *stubTransferService) List(_ context.Context, _ uint, direction, _ string, _, pageSize int) ([]*models.FileTransfer, int64, error) {
+	s.direction, s.pageSize = direction, pageSize
+	return []*models.FileTransfer{{FileID: 1}}, 1, nil
+}
+
+func (s *stubTransferService) ForceTransfer(_ context.Context, _ uint, req *file.ForceTransferRequest) (*file.ForceTransferResult, error) {
+	s.forced = req
+	return &file.ForceTransferResult{
+		Folder: &models.File{Name: "alice 的文件"},
+		Transfers: []*models.FileTransfer{
+			{FileID: 1, FromUserID: req.FromUserID, ToUserID: req.ToUserID, Status: models.TransferStatusCompleted},
+			{FileID: 5, FromUserID: req.FromUserID, ToUserID: req.ToUserID, Status: models.TransferStatusAccepted},
+		},
+		Completed: 1,
+		Failed:    1,
+	}, nil
+}
+
+func setupFileTransferRouter(service file.TransferService, userID uint64, security audit.SecurityAuditService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler := NewFileTransferHandler(service, zap.NewNop())
+	handler.SetSecurityAuditService(security)
+	transfers := router.Group("/transfers", func(c *gin.Context) {
+		c.Set("user_id", userID)
+	})
+	transfers.POST("", handler.InitiateTransfer)
+	transfers.GET("", handler.ListTransfers)
+	transfers.POST("/:id/accept", handler.AcceptTransfer)
+	return router
+}
+
+func TestFileTransferHandler_InitiateAndAccept(t *testing.T) {
+	service := &stubTransferService{}
+	security := &recordingSecurityAudit{}
+	router := setupFileTransferRouter(service, 7, security)
+
+	w := serveAdminEmail(router, http.MethodPost, "/transfers", `{"file_id":1,"to_user_id":8,"share_mode":"revoke"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, models.TransferShareRevoke, service.initiated.ShareMode)
+	require.Len(t, security.events, 1)
+	assert.Equal(t, audit.SecurityActionTransferStart, security.events[0].Action)
+	assert.Equal(t, "1", security.events[0].ResourceID)
+
+	w = serveAdminEmail(router, http.MethodPost, "/transfers", `{"file_id":1,"to_user_id":8,"share_mode":"drop"}`)
+	assert.Equal(t, utils.CodeBadRequest, decodeShareResponse(t, w).Code)
+	w = serveAdminEmail(router, http.MethodPost, "/transfers", `{"file_id":1,"to_user_id":7}`)
+	assert.Equal(t, utils.CodeValidationError, decodeShareResponse(t, w).Code)
+
+	// 发起方不能接受，不记录所有者变更
+	w = serveAdminEmail(router, http.MethodPost, "/transfers/3/accept", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Len(t, security.events, 1)
+
+	router = setupFileTransferRouter(service, 8, security)
+	w = serveAdminEmail(router, http.MethodPost, "/transfers/4/accept", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = serveAdminEmail(router, http.MethodPost, "/transfers/3/accept", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Len(t, security.events, 2)
+	event := security.events[1]
+	assert.Equal(t, audit.SecurityActionOwnerChange, event.Action)
+	assert.Equal(t, uint(7), event.Before["owner_id"])
+	assert.Equal(t, uint(8), event.After["owner_id"])
+}
+
+func TestFileTransferHandler_ListTransfers(t *testing.T) {
+	service := &stubTransferService{}
+	router := setupFileTransferRouter(service, 8, nil)
+
+	w := serveAdminEmail(router, http.MethodGet, "/transfers?direction=incoming&page_size=500", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "incoming", service.direction)
+	assert.Equal(t, maxTransferPageSize, service.pageSize)
+}
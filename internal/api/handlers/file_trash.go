@@ -0,0 +1,383 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+	"cloudpan/internal/service/audit"
+	"cloudpan/internal/service/file"
+)
+
+// 回收站列表分页参数
+const (
+	defaultTrashPageSize = 20
+	maxTrashPageSize     = 100
+)
+
+// FileTrashHandler 回收站处理器
+type FileTrashHandler struct {
+	fileAccess
+	securityAudit
+	service file.TrashService
+	logger  *zap.Logger
+}
+
+// NewFileTrashHandler 创建回收站处理器
+func NewFileTrashHandler(service file.TrashService, logger *zap.Logger) *FileTrashHandler {
+	return &FileTrashHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// MoveToTrash 删除文件，移入回收站
+//
+// @Summary 删除文件
+// @Description 将文件或文件夹(连同全部子项)移入回收站，保留期内可恢复，超过保留期后自动彻底删除。移入回收站不释放存储空间。获得delete授权的用户移入的项目进入文件所有者的回收站
+// @Tags 文件
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "文件ID"
+// @Success 200 {object} utils.Response{data=file.TrashItem} "已移入回收站"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "无权删除该文件"
+// @Failure 404 {object} utils.Response "文件不存在"
+// @Router /api/v1/files/{id} [delete]
+func (h *FileTrashHandler) MoveToTrash(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+	fileID, ok := parseIDParam(c, "id")
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "文件ID格式错误")
+		return
+	}
+
+	actingUserID, ok := h.actingUser(c, userID, fileID, models.FilePermissionDelete)
+	if !ok {
+		return
+	}
+
+	item, err := h.service.MoveToTrash(c.Request.Context(), actingUserID, fileID)
+	if err != nil {
+		respondServiceError(c, err, "删除文件失败")
+		return
+	}
+
+	h.logger.Info("File moved to trash",
+		zap.Uint("user_id", userID),
+		zap.Uint("file_id", fileID),
+		zap.Uint("trash_id", item.ID),
+		zap.String("ip", c.ClientIP()))
+	h.recordSecurity(c, h.logger, &audit.Event{
+		UserID:       userID,
+		Action:       audit.SecurityActionFileTrash,
+		ResourceType: audit.ResourceFile,
+		ResourceID:   strconv.FormatUint(uint64(fileID), 10),
+		ResourceName: item.Name,
+		Before:       map[string]interface{}{"path": item.OriginalPath, "is_folder": item.IsFolder, "size": item.Size, "owner_id": actingUserID},
+		After:        map[string]interface{}{"trash_id": item.ID, "auto_delete_at": item.AutoDeleteAt},
+	})
+	utils.Success(c, item)
+}
+
+// ListTrash 列出回收站项目
+//
+// @Summary 回收站列表
+// @Description 分页列出当前用户回收站中的项目，按删除时间倒序
+// @Tags 回收站
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "页码" default(1)
+// @Param page_size query int false "每页数量(最大100)" default(20)
+// @Success 200 {object} utils.ListResponse{data=[]file.TrashItem} "回收站项目"
+// @Failure 401 {object} utils.Response "未认证"
+// @Router /api/v1/trash [get]
+func (h *FileTrashHandler) ListTrash(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	page, _ := strconv.Atoi(c.Query("page"))
+	if page < 1 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(c.Query("page_size"))
+	if pageSize < 1 {
+		pageSize = defaultTrashPageSize
+	}
+	if pageSize > maxTrashPageSize {
+		pageSize = maxTrashPageSize
+	}
+
+	items, total, err := h.service.ListTrash(c.Request.Context(), userID, page, pageSize)
+	if err != nil {
+		respondServiceError(c, err, "获取回收站列表失败")
+		return
+	}
+
+	utils.SuccessList(c, items, utils.NewPagination(page, pageSize, total))
+}
+
+// RestoreTrashItem 恢复回收站项目
+//
+// @Summary 恢复回收站项目
+// @Description 恢复到原位置；原文件夹已不存在时恢复到根目录(relocated=true)，目标位置有同名文件时自动重命名(renamed=true)
+// @Tags 回收站
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "回收站项目ID"
+// @Success 200 {object} utils.Response{data=file.RestoredFile} "恢复结果"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "无权恢复或已超过保留期"
+// @Failure 404 {object} utils.Response "回收站项目不存在"
+// @Router /api/v1/trash/{id}/restore [post]
+func (h *FileTrashHandler) RestoreTrashItem(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+	itemID, ok := parseIDParam(c, "id")
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "回收站项目ID格式错误")
+		return
+	}
+
+	restored, err := h.service.Restore(c.Request.Context(), userID, itemID)
+	if err != nil {
+		respondServiceError(c, err, "恢复文件失败")
+		return
+	}
+
+	h.logger.Info("Trash item restored",
+		zap.Uint("user_id", userID),
+		zap.Uint("trash_id", itemID),
+		zap.Uint("file_id", restored.FileID),
+		zap.String("path", restored.Path))
+	utils.Success(c, restored)
+}
+
+// DeleteTrashItem 彻底删除回收站项目
+//
+// @Summary 彻底删除
+// @Description 彻底删除回收站项目及其存储对象并释放存储空间，不可恢复
+// @Tags 回收站
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "回收站项目ID"
+// @Success 200 {object} utils.Response "删除成功"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "无权删除"
+// @Failure 404 {object} utils.Response "回收站项目不存在"
+// @Router /api/v1/trash/{id} [delete]
+func (h *FileTrashHandler) DeleteTrashItem(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+	itemID, ok := parseIDParam(c, "id")
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "回收站项目ID格式错误")
+		return
+	}
+
+	if err := h.service.DeletePermanently(c.Request.Context(), userID, itemID); err != nil {
+		respondServiceError(c, err, "彻底删除失败")
+		return
+	}
+
+	h.logger.Info("Trash item deleted permanently",
+		zap.Uint("user_id", userID),
+		zap.Uint("trash_id", itemID),
+		zap.String("ip", c.ClientIP()))
+	h.recordSecurity(c, h.logger, &audit.Event{
+		UserID:       userID,
+		Action:       audit.SecurityActionFilePurge,
+		ResourceType: audit.ResourceTrashItem,
+		ResourceID:   strconv.FormatUint(uint64(itemID), 10),
+	})
+	utils.Success(c, nil)
+}
+
+// UpdateTrashSettingsRequest 回收站设置请求
+type UpdateTrashSettingsRequest struct {
+	RetentionDays *int `json:"retention_days" binding:"required"` // 保留天数，0表示恢复系统默认
+}
+
+// EmptyTrashRequest 清空回收站请求
+type EmptyTrashRequest struct {
+	ConfirmToken string `json:"confirm_token" binding:"required"` // 确认令牌，由确认接口签发
+}
+
+// GetTrashSettings 查询回收站设置
+//
+// @Summary 回收站设置
+// @Description 返回当前生效的回收站保留天数、系统默认值和可设置的范围
+// @Tags 回收站
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response{data=file.TrashSettings} "回收站设置"
+// @Failure 401 {object} utils.Response "未认证"
+// @Router /api/v1/trash/settings [get]
+func (h *FileTrashHandler) GetTrashSettings(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	settings, err := h.service.GetSettings(c.Request.Context(), userID)
+	if err != nil {
+		respondServiceError(c, err, "获取回收站设置失败")
+		return
+	}
+	utils.Success(c, settings)
+}
+
+// UpdateTrashSettings 修改回收站设置
+//
+// @Summary 修改回收站设置
+// @Description 在管理员设置的范围内自定义回收站保留天数，0表示恢复系统默认。新的保留天数对之后删除的文件生效，已在回收站中的项目保持原来的自动清理时间
+// @Tags 回收站
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body UpdateTrashSettingsRequest true "保留天数"
+// @Success 200 {object} utils.Response{data=file.TrashSettings} "修改后的设置"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "不支持自定义保留时长"
+// @Failure 422 {object} utils.Response "保留天数超出范围"
+// @Router /api/v1/trash/settings [put]
+func (h *FileTrashHandler) UpdateTrashSettings(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+	var req UpdateTrashSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+		return
+	}
+
+	settings, err := h.service.UpdateSettings(c.Request.Context(), userID, *req.RetentionDays)
+	if err != nil {
+		respondServiceError(c, err, "修改回收站设置失败")
+		return
+	}
+	utils.Success(c, settings)
+}
+
+// ConfirmEmptyTrash 获取清空回收站的确认令牌
+//
+// @Summary 确认清空回收站
+// @Description 统计回收站中将被彻底删除的项目数和将释放的空间，签发5分钟内有效、只能使用一次的确认令牌。清空时只删除签发令牌时已在回收站中的项目
+// @Tags 回收站
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response{data=file.EmptyTrashConfirmation} "确认信息和令牌"
+// @Failure 401 {object} utils.Response "未认证"
+// @Router /api/v1/trash/empty/confirmation [post]
+func (h *FileTrashHandler) ConfirmEmptyTrash(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	confirmation, err := h.service.PrepareEmpty(c.Request.Context(), userID)
+	if err != nil {
+		respondServiceError(c, err, "统计回收站失败")
+		return
+	}
+	utils.Success(c, confirmation)
+}
+
+// EmptyTrash 清空回收站
+//
+// @Summary 清空回收站
+// @Description 凭确认令牌彻底删除回收站中的项目并释放存储空间，不可恢复。项目较多时在后台执行，返回running状态的任务，通过任务接口查询进度
+// @Tags 回收站
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body EmptyTrashRequest true "确认令牌"
+// @Success 200 {object} utils.Response{data=file.EmptyTrashJob} "清空任务"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 409 {object} utils.Response "回收站正在清空"
+// @Failure 422 {object} utils.Response "确认令牌无效或已过期"
+// @Router /api/v1/trash/empty [post]
+func (h *FileTrashHandler) EmptyTrash(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+	var req EmptyTrashRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+		return
+	}
+
+	job, err := h.service.EmptyTrash(c.Request.Context(), userID, req.ConfirmToken)
+	if err != nil {
+		respondServiceError(c, err, "清空回收站失败")
+		return
+	}
+
+	h.logger.Info("Trash empty requested",
+		zap.Uint("user_id", userID),
+		zap.String("job_id", job.ID),
+		zap.Int64("items", job.Total),
+		zap.String("ip", c.ClientIP()))
+	h.recordSecurity(c, h.logger, &audit.Event{
+		UserID:       userID,
+		Action:       audit.SecurityActionTrashEmpty,
+		ResourceType: audit.ResourceUser,
+		ResourceID:   strconv.FormatUint(uint64(userID), 10),
+		Details:      map[string]interface{}{"job_id": job.ID, "items": job.Total, "async": job.Async},
+	})
+	utils.Success(c, job)
+}
+
+// GetEmptyTrashJob 查询清空回收站任务进度
+//
+// @Summary 清空回收站进度
+// @Description 查询清空回收站任务的进度和释放的空间，任务结束24小时后不再保留
+// @Tags 回收站
+// @Produce json
+// @Security BearerAuth
+// @Param job_id path string true "任务ID"
+// @Success 200 {object} utils.Response{data=file.EmptyTrashJob} "任务进度"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 404 {object} utils.Response "任务不存在或已过期"
+// @Router /api/v1/trash/empty/{job_id} [get]
+func (h *FileTrashHandler) GetEmptyTrashJob(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	job, err := h.service.GetEmptyJob(c.Request.Context(), userID, c.Param("job_id"))
+	if err != nil {
+		respondServiceError(c, err, "查询清空进度失败")
+		return
+	}
+	utils.Success(c, job)
+}
@@ -0,0 +1,274 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/service/file"
+)
+
+// MockTrashService 模拟回收站服务
+type MockTrashService struct {
+	mock.Mock
+}
+
+func (m *MockTrashService) MoveToTrash(ctx context.Context, userID, fileID uint) (*file.TrashItem, error) {
+	args := m.Called(ctx, userID, fileID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*file.TrashItem), args.Error(1)
+}
+
+func (m *MockTrashService) ListTrash(ctx context.Context, userID uint, page, pageSize int) ([]*file.TrashItem, int64, error) {
+	args := m.Called(ctx, userID, page, pageSize)
+	if args.Get(0) == nil {
+		return nil, 0, args.Error(2)
+	}
+	return args.Get(0).([]*file.TrashItem), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockTrashService) Restore(ctx context.Context, userID, itemID uint) (*file.RestoredFile, error) {
+	args := m.Called(ctx, userID, itemID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*file.RestoredFile), args.Error(1)
+}
+
+func (m *MockTrashService) DeletePermanently(ctx context.Context, userID, itemID uint) error {
+	args := m.Called(ctx, userID, itemID)
+	return args.Error(0)
+}
+
+func (m *MockTrashService) PurgeExpired(ctx context.Context, limit int) (int64, error) {
+	args := m.Called(ctx, limit)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockTrashService) GetSettings(ctx context.Context, userID uint) (*file.TrashSettings, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*file.TrashSettings), args.Error(1)
+}
+
+func (m *MockTrashService) UpdateSettings(ctx context.Context, userID uint, retentionDays int) (*file.TrashSettings, error) {
+	args := m.Called(ctx, userID, retentionDays)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*file.TrashSettings), args.Error(1)
+}
+
+func (m *MockTrashService) PrepareEmpty(ctx context.Context, userID uint) (*file.EmptyTrashConfirmation, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*file.EmptyTrashConfirmation), args.Error(1)
+}
+
+func (m *MockTrashService) EmptyTrash(ctx context.Context, userID uint, token string) (*file.EmptyTrashJob, error) {
+	args := m.Called(ctx, userID, token)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*file.EmptyTrashJob), args.Error(1)
+}
+
+func (m *MockTrashService) GetEmptyJob(ctx context.Context, userID uint, jobID string) (*file.EmptyTrashJob, error) {
+	args := m.Called(ctx, userID, jobID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*file.EmptyTrashJob), args.Error(1)
+}
+
+func setupFileTrashRouter(service *MockTrashService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler := NewFileTrashHandler(service, zap.NewNop())
+	authed := router.Group("", func(c *gin.Context) {
+		c.Set("user_id", uint64(7))
+	})
+	authed.DELETE("/files/:id", handler.MoveToTrash)
+	authed.GET("/trash", handler.ListTrash)
+	authed.POST("/trash/:id/restore", handler.RestoreTrashItem)
+	authed.DELETE("/trash/:id", handler.DeleteTrashItem)
+	authed.GET("/trash/settings", handler.GetTrashSettings)
+	authed.PUT("/trash/settings", handler.UpdateTrashSettings)
+	authed.POST("/trash/empty/confirmation", handler.ConfirmEmptyTrash)
+	authed.POST("/trash/empty", handler.EmptyTrash)
+	authed.GET("/trash/empty/:job_id", handler.GetEmptyTrashJob)
+	return router
+}
+
+func TestFileTrashHandler_MoveToTrash(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		service := new(MockTrashService)
+		service.On("MoveToTrash", mock.Anything, uint(7), uint(3)).
+			Return(&file.TrashItem{ID: 11, FileID: 3, Name: "a.txt"}, nil)
+
+		w := httptest.NewRecorder()
+		setupFileTrashRouter(service).ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/files/3", nil))
+
+		require.Equal(t, http.StatusOK, w.Code)
+		service.AssertExpectations(t)
+	})
+
+	t.Run("invalid id", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		setupFileTrashRouter(new(MockTrashService)).ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/files/abc", nil))
+
+		assert.Equal(t, utils.CodeBadRequest, decodeShareResponse(t, w).Code)
+	})
+}
+
+func TestFileTrashHandler_ListTrash(t *testing.T) {
+	service := new(MockTrashService)
+	service.On("ListTrash", mock.Anything, uint(7), 2, maxTrashPageSize).
+		Return([]*file.TrashItem{{ID: 11}}, int64(101), nil)
+
+	w := httptest.NewRecorder()
+	setupFileTrashRouter(service).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/trash?page=2&page_size=500", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Data       []file.TrashItem `json:"data"`
+		Pagination utils.Pagination `json:"pagination"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Len(t, resp.Data, 1)
+	assert.Equal(t, int64(101), resp.Pagination.TotalCount)
+	assert.Equal(t, 2, resp.Pagination.TotalPages)
+}
+
+func TestFileTrashHandler_RestoreTrashItem(t *testing.T) {
+	t.Run("relocated", func(t *testing.T) {
+		service := new(MockTrashService)
+		service.On("Restore", mock.Anything, uint(7), uint(11)).
+			Return(&file.RestoredFile{FileID: 3, Name: "a (1).txt", Path: "/a (1).txt", Relocated: true, Renamed: true}, nil)
+
+		w := httptest.NewRecorder()
+		setupFileTrashRouter(service).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/trash/11/restore", nil))
+
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Data file.RestoredFile `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.True(t, resp.Data.Relocated)
+		assert.Equal(t, "/a (1).txt", resp.Data.Path)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		service := new(MockTrashService)
+		service.On("Restore", mock.Anything, uint(7), uint(11)).
+			Return(nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "回收站项目不存在"))
+
+		w := httptest.NewRecorder()
+		setupFileTrashRouter(service).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/trash/11/restore", nil))
+
+		assert.Equal(t, utils.CodeNotFound, decodeShareResponse(t, w).Code)
+	})
+}
+
+func TestFileTrashHandler_DeleteTrashItem(t *testing.T) {
+	service := new(MockTrashService)
+	service.On("DeletePermanently", mock.Anything, uint(7), uint(11)).Return(nil)
+
+	w := httptest.NewRecorder()
+	setupFileTrashRouter(service).ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/trash/11", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	service.AssertExpectations(t)
+}
+
+func TestFileTrashHandler_Settings(t *testing.T) {
+	service := new(MockTrashService)
+	service.On("GetSettings", mock.Anything, uint(7)).
+		Return(&file.TrashSettings{RetentionDays: 30, DefaultDays: 30, MinDays: 1, MaxDays: 90}, nil)
+	service.On("UpdateSettings", mock.Anything, uint(7), 7).
+		Return(&file.TrashSettings{RetentionDays: 7, Custom: true, DefaultDays: 30, MinDays: 1, MaxDays: 90}, nil)
+	service.On("UpdateSettings", mock.Anything, uint(7), 91).
+		Return(nil, pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "回收站保留天数必须在1到90之间"))
+	router := setupFileTrashRouter(service)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/trash/settings", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	put := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, "/trash/settings", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+	w = put(`{"retention_days":7}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Data file.TrashSettings `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.True(t, resp.Data.Custom)
+
+	assert.Equal(t, utils.CodeValidationError, decodeShareResponse(t, put(`{"retention_days":91}`)).Code)
+	assert.Equal(t, utils.CodeBadRequest, decodeShareResponse(t, put(`{}`)).Code)
+	service.AssertExpectations(t)
+}
+
+func TestFileTrashHandler_EmptyTrash(t *testing.T) {
+	service := new(MockTrashService)
+	service.On("PrepareEmpty", mock.Anything, uint(7)).
+		Return(&file.EmptyTrashConfirmation{Token: "tok", Items: 150, Size: 1024, Async: true}, nil)
+	service.On("EmptyTrash", mock.Anything, uint(7), "tok").
+		Return(&file.EmptyTrashJob{ID: "job", Status: file.EmptyTrashJobRunning, Async: true, Total: 150}, nil)
+	service.On("EmptyTrash", mock.Anything, uint(7), "stale").
+		Return(nil, pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "确认令牌无效或已过期，请重新确认"))
+	service.On("GetEmptyJob", mock.Anything, uint(7), "job").
+		Return(&file.EmptyTrashJob{ID: "job", Status: file.EmptyTrashJobCompleted, Purged: 150, Reclaimed: 1024}, nil)
+	router := setupFileTrashRouter(service)
+	post := func(path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post("/trash/empty/confirmation", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"confirm_token":"tok"`)
+
+	w = post("/trash/empty", `{"confirm_token":"tok"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Data file.EmptyTrashJob `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, file.EmptyTrashJobRunning, resp.Data.Status)
+
+	assert.Equal(t, utils.CodeValidationError, decodeShareResponse(t, post("/trash/empty", `{"confirm_token":"stale"}`)).Code)
+	assert.Equal(t, utils.CodeBadRequest, decodeShareResponse(t, post("/trash/empty", `{}`)).Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/trash/empty/job", nil))
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, int64(1024), resp.Data.Reclaimed)
+	service.AssertExpectations(t)
+}
@@ -0,0 +1,411 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/utils"
+	filerepo "cloudpan/internal/repository/file"
+	"cloudpan/internal/repository/models"
+	"cloudpan/internal/service/file"
+)
+
+// 文件列表分页参数
+const (
+	defaultFilePageSize = 50
+	maxFilePageSize     = 200
+	maxFilterTags       = 10 // 文件列表最多按多少个标签筛选
+)
+
+// fileSortFields 文件列表支持的排序字段，其他字段按名称升序排序
+var fileSortFields = []string{
+	filerepo.ContentsOrderName,
+	filerepo.ContentsOrderSize,
+	filerepo.ContentsOrderCreatedAt,
+	filerepo.ContentsOrderUpdatedAt,
+}
+
+// fileScanStatuses 文件列表支持筛选的病毒扫描状态
+var fileScanStatuses = []string{
+	models.ScanStatusPending,
+	models.ScanStatusClean,
+	models.ScanStatusInfected,
+	models.ScanStatusFailed,
+	models.ScanStatusSkipped,
+}
+
+// filePreviewStatuses 文件列表支持筛选的预览生成状态
+var filePreviewStatuses = []string{
+	models.PreviewStatusNone,
+	models.PreviewStatusPending,
+	models.PreviewStatusReady,
+	models.PreviewStatusFailed,
+}
+
+// UploadProgressAttacher 为文件列表中的上传占位文件填充上传进度，由 file.ChunkedUploadService 实现
+type UploadProgressAttacher interface {
+	AttachProgress(ctx context.Context, files []*models.File) error
+}
+
+// FileTreeHandler 文件浏览、新建文件夹、重命名、移动和复制处理器
+type FileTreeHandler struct {
+	fileAccess
+	service file.TreeService
+	uploads UploadProgressAttacher
+	logger  *zap.Logger
+}
+
+// NewFileTreeHandler 创建文件浏览、新建文件夹、重命名、移动和复制处理器
+func NewFileTreeHandler(service file.TreeService, logger *zap.Logger) *FileTreeHandler {
+	return &FileTreeHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// SetUploadProgress 设置上传进度查询，未设置时列表中的上传占位文件不带进度
+func (h *FileTreeHandler) SetUploadProgress(uploads UploadProgressAttacher) {
+	h.uploads = uploads
+}
+
+// RenameFileRequest 重命名请求
+type RenameFileRequest struct {
+	Name string `json:"name" binding:"required"` // 新名称
+}
+
+// MoveFileRequest 移动或复制请求
+type MoveFileRequest struct {
+	ParentID *uint `json:"parent_id"` // 目标文件夹ID，为空表示根目录
+}
+
+// CreateFolderRequest 新建文件夹请求
+type CreateFolderRequest struct {
+	Name     string `json:"name" binding:"required"` // 文件夹名称
+	ParentID *uint  `json:"parent_id"`               // 上级文件夹ID，为空表示根目录
+}
+
+// ListFiles 列出文件夹内容
+//
+// @Summary 列出文件夹内容
+// @Description 分页列出文件夹下的文件和子文件夹，文件夹排在文件之前，同类按排序字段排序(默认按名称升序)。不传parent_id时列出根目录；获得read授权的用户可以浏览他人的文件夹。
+// @Description 名称排序规则：binary按字节排序；natural自然排序(file2在file10之前)；locale按语言习惯排序(中文按拼音)。
+// @Description natural和locale只在按名称排序且文件夹子项不超过上限时生效，否则按字节排序。
+// @Description 每个文件带有processing处理状态(病毒扫描、预览生成、搜索索引)，指定scan_status或preview_status时只返回状态匹配的文件，不返回文件夹
+// @Description 默认同时返回其他设备正在分片上传的文件(status为uploading)，带有upload_progress上传进度；按处理状态筛选时不返回
+// @Description 指定tags时只返回带有这些标签的文件和文件夹，tag_mode为all(默认)时要求带有全部标签，为any时带有任一标签即可
+// @Tags 文件
+// @Produce json
+// @Security BearerAuth
+// @Param parent_id query int false "文件夹ID，为空表示根目录"
+// @Param page query int false "页码" default(1)
+// @Param page_size query int false "每页数量(最大200)" default(50)
+// @Param sort_by query string false "排序字段" Enums(name, size, created_at, updated_at) default(name)
+// @Param sort_dir query string false "排序方向，指定排序字段时默认desc" Enums(asc, desc)
+// @Param collation query string false "名称排序规则" Enums(binary, natural, locale) default(binary)
+// @Param locale query string false "locale排序规则使用的语言，默认为请求语言" example(zh-CN)
+// @Param scan_status query string false "按病毒扫描状态筛选" Enums(pending, clean, infected, failed, skipped)
+// @Param preview_status query string false "按预览生成状态筛选，none表示不生成预览" Enums(none, pending, ready, failed)
+// @Param include_uploading query bool false "是否返回正在上传的文件" default(true)
+// @Param tags query string false "按标签筛选，多个标签以逗号分隔(最多10个)" example(合同,2024)
+// @Param tag_mode query string false "多个标签的匹配方式" Enums(all, any) default(all)
+// @Success 200 {object} utils.ListResponse{data=[]models.File} "文件列表"
+// @Failure 400 {object} utils.Response "请求参数错误或目标不是文件夹"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "无权访问该文件夹"
+// @Failure 404 {object} utils.Response "文件夹不存在"
+// @Router /api/v1/files [get]
+func (h *FileTreeHandler) ListFiles(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	var parentID *uint
+	if value := c.Query("parent_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 64)
+		if err != nil || id == 0 {
+			utils.ErrorWithMessage(c, utils.CodeBadRequest, "文件夹ID格式错误")
+			return
+		}
+		folderID := uint(id)
+		parentID = &folderID
+	}
+
+	page, _ := strconv.Atoi(c.Query("page"))
+	if page < 1 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(c.Query("page_size"))
+	if pageSize < 1 {
+		pageSize = defaultFilePageSize
+	}
+	if pageSize > maxFilePageSize {
+		pageSize = maxFilePageSize
+	}
+
+	pageReq := utils.ParsePageRequest(c)
+	options := file.ListOptions{Page: page, PageSize: pageSize, Collation: pageReq.Collation, Locale: pageReq.Locale}
+	if pageReq.ValidateSortField(fileSortFields) {
+		options.SortBy = pageReq.SortBy
+		options.Desc = pageReq.SortDir == "desc"
+	}
+	options.ScanStatus = c.Query("scan_status")
+	if options.ScanStatus != "" && !slices.Contains(fileScanStatuses, options.ScanStatus) {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "病毒扫描状态不支持")
+		return
+	}
+	options.PreviewStatus = c.Query("preview_status")
+	if options.PreviewStatus != "" && !slices.Contains(filePreviewStatuses, options.PreviewStatus) {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "预览状态不支持")
+		return
+	}
+	options.IncludeUploading = true
+	if value := c.Query("include_uploading"); value != "" {
+		include, err := strconv.ParseBool(value)
+		if err != nil {
+			utils.ErrorWithMessage(c, utils.CodeBadRequest, "include_uploading参数格式错误")
+			return
+		}
+		options.IncludeUploading = include
+	}
+	if value := c.Query("tags"); value != "" {
+		for _, tag := range strings.Split(value, ",") {
+			tag, err := file.NormalizeTag(tag)
+			if err != nil {
+				utils.ErrorWithMessage(c, utils.CodeBadRequest, "标签格式错误")
+				return
+			}
+			if !slices.Contains(options.Tags, tag) {
+				options.Tags = append(options.Tags, tag)
+			}
+		}
+		if len(options.Tags) > maxFilterTags {
+			utils.ErrorWithMessage(c, utils.CodeBadRequest, fmt.Sprintf("最多按%d个标签筛选", maxFilterTags))
+			return
+		}
+	}
+	switch c.DefaultQuery("tag_mode", "all") {
+	case "all":
+	case "any":
+		options.MatchAnyTag = true
+	default:
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "标签匹配方式不支持")
+		return
+	}
+
+	actingUserID := userID
+	if parentID != nil {
+		if actingUserID, ok = h.actingUser(c, userID, *parentID, models.FilePermissionRead); !ok {
+			return
+		}
+	}
+
+	files, total, err := h.service.List(c.Request.Context(), actingUserID, parentID, options)
+	if err != nil {
+		respondServiceError(c, err, "获取文件列表失败")
+		return
+	}
+	if h.uploads != nil && options.IncludeUploading {
+		if err := h.uploads.AttachProgress(c.Request.Context(), files); err != nil {
+			// 进度只是附加信息，查询失败时仍返回列表
+			h.logger.Warn("Failed to attach upload progress", zap.Uint("user_id", actingUserID), zap.Error(err))
+		}
+	}
+
+	utils.SuccessList(c, files, utils.NewPagination(page, pageSize, total))
+}
+
+// CreateFolder 新建文件夹
+//
+// @Summary 新建文件夹
+// @Description 在目标文件夹下新建子文件夹，同一文件夹下不允许重名。获得write授权的用户可以在他人的文件夹下新建，新文件夹属于该文件夹的所有者
+// @Tags 文件
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body CreateFolderRequest true "文件夹名称和位置"
+// @Success 200 {object} utils.Response{data=models.File} "新建的文件夹"
+// @Failure 400 {object} utils.Response "请求参数错误、名称不合法或目标不是文件夹"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "无权访问目标文件夹"
+// @Failure 404 {object} utils.Response "目标文件夹不存在"
+// @Failure 409 {object} utils.Response "已存在同名文件"
+// @Router /api/v1/files/folders [post]
+func (h *FileTreeHandler) CreateFolder(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	var req CreateFolderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+		return
+	}
+
+	actingUserID := userID
+	if req.ParentID != nil {
+		if actingUserID, ok = h.actingUser(c, userID, *req.ParentID, models.FilePermissionWrite); !ok {
+			return
+		}
+	}
+
+	folder, err := h.service.CreateFolder(c.Request.Context(), actingUserID, req.ParentID, req.Name)
+	if err != nil {
+		respondServiceError(c, err, "新建文件夹失败")
+		return
+	}
+
+	utils.Success(c, folder)
+}
+
+// RenameFile 重命名文件或文件夹
+//
+// @Summary 重命名文件
+// @Description 重命名文件或文件夹，同一文件夹下不允许重名，文件夹的全部子项路径随之更新
+// @Tags 文件
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "文件ID"
+// @Param request body RenameFileRequest true "新名称"
+// @Success 200 {object} utils.Response{data=models.File} "重命名后的文件"
+// @Failure 400 {object} utils.Response "请求参数错误或文件名不合法"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "无权操作该文件"
+// @Failure 404 {object} utils.Response "文件不存在"
+// @Failure 409 {object} utils.Response "已存在同名文件"
+// @Router /api/v1/files/{id} [patch]
+func (h *FileTreeHandler) RenameFile(c *gin.Context) {
+	userID, fileID, ok := h.parseTarget(c)
+	if !ok {
+		return
+	}
+
+	var req RenameFileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+		return
+	}
+
+	actingUserID, ok := h.actingUser(c, userID, fileID, models.FilePermissionWrite)
+	if !ok {
+		return
+	}
+
+	renamed, err := h.service.Rename(c.Request.Context(), actingUserID, fileID, req.Name)
+	if err != nil {
+		respondServiceError(c, err, "重命名失败")
+		return
+	}
+
+	utils.Success(c, renamed)
+}
+
+// MoveFile 移动文件或文件夹
+//
+// @Summary 移动文件
+// @Description 将文件或文件夹移动到目标文件夹，文件夹的全部子项随之移动。不能移动到自身或子文件夹中，目标位置不能有同名文件。获得授权的用户需要对文件和目标文件夹都有write权限，且两者属于同一所有者
+// @Tags 文件
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "文件ID"
+// @Param request body MoveFileRequest true "目标文件夹"
+// @Success 200 {object} utils.Response{data=models.File} "移动后的文件"
+// @Failure 400 {object} utils.Response "请求参数错误或目标不是文件夹"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "无权操作或目标是自身的子文件夹"
+// @Failure 404 {object} utils.Response "文件或目标文件夹不存在"
+// @Failure 409 {object} utils.Response "目标位置已存在同名文件"
+// @Router /api/v1/files/{id}/move [post]
+func (h *FileTreeHandler) MoveFile(c *gin.Context) {
+	userID, fileID, ok := h.parseTarget(c)
+	if !ok {
+		return
+	}
+
+	var req MoveFileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+		return
+	}
+
+	actingUserID, ok := h.actingUser(c, userID, fileID, models.FilePermissionWrite)
+	if !ok || !h.checkTarget(c, userID, actingUserID, req.ParentID, models.FilePermissionWrite) {
+		return
+	}
+
+	moved, err := h.service.Move(c.Request.Context(), actingUserID, fileID, req.ParentID)
+	if err != nil {
+		respondServiceError(c, err, "移动文件失败")
+		return
+	}
+
+	utils.Success(c, moved)
+}
+
+// CopyFile 复制文件或文件夹
+//
+// @Summary 复制文件
+// @Description 将文件或文件夹(连同全部子项)复制到目标文件夹，目标位置重名时自动追加序号。复制占用存储空间。获得授权的用户需要对文件有read权限、对目标文件夹有write权限，且两者属于同一所有者
+// @Tags 文件
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "文件ID"
+// @Param request body MoveFileRequest true "目标文件夹"
+// @Success 200 {object} utils.Response{data=models.File} "复制出的文件"
+// @Failure 400 {object} utils.Response "请求参数错误或目标不是文件夹"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "无权操作、目标是自身的子文件夹、包含已归档文件或存储空间不足"
+// @Failure 404 {object} utils.Response "文件或目标文件夹不存在"
+// @Router /api/v1/files/{id}/copy [post]
+func (h *FileTreeHandler) CopyFile(c *gin.Context) {
+	userID, fileID, ok := h.parseTarget(c)
+	if !ok {
+		return
+	}
+
+	var req MoveFileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+		return
+	}
+
+	actingUserID, ok := h.actingUser(c, userID, fileID, models.FilePermissionRead)
+	if !ok || !h.checkTarget(c, userID, actingUserID, req.ParentID, models.FilePermissionWrite) {
+		return
+	}
+
+	copied, err := h.service.Copy(c.Request.Context(), actingUserID, fileID, req.ParentID)
+	if err != nil {
+		respondServiceError(c, err, "复制文件失败")
+		return
+	}
+
+	utils.Success(c, copied)
+}
+
+// parseTarget 解析当前用户和路径中的文件ID，失败时已写入响应
+func (h *FileTreeHandler) parseTarget(c *gin.Context) (uint, uint, bool) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return 0, 0, false
+	}
+	fileID, ok := parseIDParam(c, "id")
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "文件ID格式错误")
+		return 0, 0, false
+	}
+	return userID, fileID, true
+}
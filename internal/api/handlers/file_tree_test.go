@@ -0,0 +1,389 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+	"cloudpan/internal/service/file"
+)
+
+// MockTreeService 模拟文件树操作服务
+type MockTreeService struct {
+	mock.Mock
+}
+
+func (m *MockTreeService) Rename(ctx context.Context, userID, fileID uint, name string) (*models.File, error) {
+	args := m.Called(ctx, userID, fileID, name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.File), args.Error(1)
+}
+
+func (m *MockTreeService) Move(ctx context.Context, userID, fileID uint, targetParentID *uint) (*models.File, error) {
+	args := m.Called(ctx, userID, fileID, targetParentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.File), args.Error(1)
+}
+
+func (m *MockTreeService) Copy(ctx context.Context, userID, fileID uint, targetParentID *uint) (*models.File, error) {
+	args := m.Called(ctx, userID, fileID, targetParentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.File), args.Error(1)
+}
+
+func (m *MockTreeService) List(ctx context.Context, userID uint, parentID *uint, options file.ListOptions) ([]*models.File, int64, error) {
+	args := m.Called(ctx, userID, parentID, options)
+	if args.Get(0) == nil {
+		return nil, 0, args.Error(2)
+	}
+	return args.Get(0).([]*models.File), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockTreeService) CreateFolder(ctx context.Context, userID uint, parentID *uint, name string) (*models.File, error) {
+	args := m.Called(ctx, userID, parentID, name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.File), args.Error(1)
+}
+
+func setupFileTreeRouter(service *MockTreeService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler := NewFileTreeHandler(service, zap.NewNop())
+	authed := router.Group("", func(c *gin.Context) {
+		c.Set("user_id", uint64(7))
+	})
+	authed.GET("/files", handler.ListFiles)
+	authed.POST("/files/folders", handler.CreateFolder)
+	authed.PATCH("/files/:id", handler.RenameFile)
+	authed.POST("/files/:id/move", handler.MoveFile)
+	authed.POST("/files/:id/copy", handler.CopyFile)
+	return router
+}
+
+func TestFileTreeHandler_RenameFile(t *testing.T) {
+	t.Run("name conflict", func(t *testing.T) {
+		service := new(MockTreeService)
+		service.On("Rename", mock.Anything, uint(7), uint(3), "b.txt").
+			Return(nil, pkgErrors.WrapError(pkgErrors.ErrResourceExists, "目标文件夹中已存在同名文件"))
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPatch, "/files/3", strings.NewReader(`{"name":"b.txt"}`))
+		setupFileTreeRouter(service).ServeHTTP(w, req)
+
+		assert.Equal(t, utils.CodeConflict, decodeShareResponse(t, w).Code)
+	})
+
+	t.Run("missing name", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPatch, "/files/3", strings.NewReader(`{}`))
+		setupFileTreeRouter(new(MockTreeService)).ServeHTTP(w, req)
+
+		assert.Equal(t, utils.CodeBadRequest, decodeShareResponse(t, w).Code)
+	})
+}
+
+func TestFileTreeHandler_MoveFile(t *testing.T) {
+	t.Run("into folder", func(t *testing.T) {
+		service := new(MockTreeService)
+		service.On("Move", mock.Anything, uint(7), uint(3), mock.MatchedBy(func(id *uint) bool { return id != nil && *id == 5 })).
+			Return(&models.File{Name: "docs", Path: "/archive"}, nil)
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/files/3/move", strings.NewReader(`{"parent_id":5}`))
+		setupFileTreeRouter(service).ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		service.AssertExpectations(t)
+	})
+
+	t.Run("into own subfolder", func(t *testing.T) {
+		service := new(MockTreeService)
+		service.On("Move", mock.Anything, uint(7), uint(3), mock.Anything).
+			Return(nil, pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "不能将文件夹移动或复制到其自身或子文件夹中"))
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/files/3/move", strings.NewReader(`{"parent_id":4}`))
+		setupFileTreeRouter(service).ServeHTTP(w, req)
+
+		assert.Equal(t, utils.CodeForbidden, decodeShareResponse(t, w).Code)
+	})
+}
+
+func TestFileTreeHandler_CopyFile(t *testing.T) {
+	service := new(MockTreeService)
+	service.On("Copy", mock.Anything, uint(7), uint(3), (*uint)(nil)).
+		Return(&models.File{Name: "a (1).txt", Path: "/"}, nil)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/files/3/copy", strings.NewReader(`{"parent_id":null}`))
+	setupFileTreeRouter(service).ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	service.AssertExpectations(t)
+}
+
+func TestFileTreeHandler_ListFiles(t *testing.T) {
+	t.Run("folder with paging", func(t *testing.T) {
+		service := new(MockTreeService)
+		service.On("List", mock.Anything, uint(7), mock.MatchedBy(func(id *uint) bool { return id != nil && *id == 5 }),
+			file.ListOptions{Page: 2, PageSize: maxFilePageSize, Collation: utils.CollationBinary, IncludeUploading: true}).
+			Return([]*models.File{{Name: "a.txt"}}, int64(201), nil)
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/files?parent_id=5&page=2&page_size=1000", nil)
+		setupFileTreeRouter(service).ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"total_count":201`)
+		service.AssertExpectations(t)
+	})
+
+	t.Run("root", func(t *testing.T) {
+		service := new(MockTreeService)
+		service.On("List", mock.Anything, uint(7), (*uint)(nil), file.ListOptions{Page: 1, PageSize: defaultFilePageSize, Collation: utils.CollationBinary, IncludeUploading: true}).
+			Return([]*models.File{}, int64(0), nil)
+
+		w := httptest.NewRecorder()
+		setupFileTreeRouter(service).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/files", nil))
+
+		require.Equal(t, http.StatusOK, w.Code)
+		service.AssertExpectations(t)
+	})
+
+	t.Run("sort options", func(t *testing.T) {
+		service := new(MockTreeService)
+		service.On("List", mock.Anything, uint(7), (*uint)(nil), file.ListOptions{
+			Page: 1, PageSize: defaultFilePageSize, SortBy: "name", Desc: true, Collation: utils.CollationLocale, Locale: "zh-CN", IncludeUploading: true,
+		}).Return([]*models.File{}, int64(0), nil)
+		service.On("List", mock.Anything, uint(7), (*uint)(nil), file.ListOptions{
+			Page: 1, PageSize: defaultFilePageSize, Collation: utils.CollationNatural, IncludeUploading: true,
+		}).Return([]*models.File{}, int64(0), nil)
+
+		router := setupFileTreeRouter(service)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/files?sort_by=name&sort_dir=desc&collation=locale&locale=zh-CN", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		// 不支持的排序字段按名称升序排序
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/files?sort_by=path&collation=natural", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		service.AssertExpectations(t)
+	})
+
+	t.Run("invalid parent", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		setupFileTreeRouter(new(MockTreeService)).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/files?parent_id=x", nil))
+
+		assert.Equal(t, utils.CodeBadRequest, decodeShareResponse(t, w).Code)
+	})
+
+	t.Run("processing filters", func(t *testing.T) {
+		service := new(MockTreeService)
+		service.On("List", mock.Anything, uint(7), (*uint)(nil), file.ListOptions{
+			Page: 1, PageSize: defaultFilePageSize, Collation: utils.CollationBinary,
+			ScanStatus: models.ScanStatusInfected, PreviewStatus: models.PreviewStatusNone, IncludeUploading: true,
+		}).Return([]*models.File{{Name: "a.exe", Status: "active", ScanStatus: models.ScanStatusInfected}}, int64(1), nil)
+
+		w := httptest.NewRecorder()
+		setupFileTreeRouter(service).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/files?scan_status=infected&preview_status=none", nil))
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"processing":{"scan":"infected","preview":"none","indexing":"done","completed":true}`)
+		service.AssertExpectations(t)
+	})
+
+	t.Run("uploading placeholders", func(t *testing.T) {
+		service := new(MockTreeService)
+		placeholder := &models.File{Name: "disk.iso", Status: models.FileStatusUploading, UploadStatus: models.UploadStatusUploading}
+		files := []*models.File{placeholder}
+		service.On("List", mock.Anything, uint(7), (*uint)(nil), file.ListOptions{
+			Page: 1, PageSize: defaultFilePageSize, Collation: utils.CollationBinary, IncludeUploading: true,
+		}).Return(files, int64(1), nil)
+		uploads := new(MockChunkedUploadService)
+		uploads.On("AttachProgress", mock.Anything, files).Run(func(mock.Arguments) {
+			placeholder.UploadProgress = &models.FileUploadProgress{ReceivedChunks: 1, TotalChunks: 3}
+		}).Return(nil)
+
+		router := gin.New()
+		handler := NewFileTreeHandler(service, zap.NewNop())
+		handler.SetUploadProgress(uploads)
+		router.GET("/files", func(c *gin.Context) {
+			c.Set("user_id", uint64(7))
+		}, handler.ListFiles)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/files", nil))
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"upload_progress":{"received_chunks":1,"total_chunks":3`)
+		uploads.AssertExpectations(t)
+	})
+
+	t.Run("exclude uploading", func(t *testing.T) {
+		service := new(MockTreeService)
+		service.On("List", mock.Anything, uint(7), (*uint)(nil), file.ListOptions{
+			Page: 1, PageSize: defaultFilePageSize, Collation: utils.CollationBinary,
+		}).Return([]*models.File{}, int64(0), nil)
+
+		router := setupFileTreeRouter(service)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/files?include_uploading=false", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		service.AssertExpectations(t)
+
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/files?include_uploading=maybe", nil))
+		assert.Equal(t, utils.CodeBadRequest, decodeShareResponse(t, w).Code)
+	})
+
+	t.Run("tag filters", func(t *testing.T) {
+		service := new(MockTreeService)
+		service.On("List", mock.Anything, uint(7), (*uint)(nil), file.ListOptions{
+			Page: 1, PageSize: defaultFilePageSize, Collation: utils.CollationBinary, IncludeUploading: true,
+			Tags: []string{"合同", "2024"},
+		}).Return([]*models.File{}, int64(0), nil)
+		service.On("List", mock.Anything, uint(7), (*uint)(nil), file.ListOptions{
+			Page: 1, PageSize: defaultFilePageSize, Collation: utils.CollationBinary, IncludeUploading: true,
+			Tags: []string{"合同"}, MatchAnyTag: true,
+		}).Return([]*models.File{}, int64(0), nil)
+
+		router := setupFileTreeRouter(service)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/files?tags="+url.QueryEscape(" 合同 ,2024,合同"), nil))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/files?tag_mode=any&tags="+url.QueryEscape("合同"), nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		service.AssertExpectations(t)
+
+		tooMany := make([]string, maxFilterTags+1)
+		for i := range tooMany {
+			tooMany[i] = strconv.Itoa(i)
+		}
+		for _, query := range []string{"tags=a,,b", "tag_mode=some", "tags=" + strings.Join(tooMany, ",")} {
+			w = httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/files?"+query, nil))
+			assert.Equal(t, utils.CodeBadRequest, decodeShareResponse(t, w).Code, query)
+		}
+	})
+
+	t.Run("invalid processing filter", func(t *testing.T) {
+		router := setupFileTreeRouter(new(MockTreeService))
+		for _, query := range []string{"scan_status=unknown", "preview_status=done"} {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/files?"+query, nil))
+			assert.Equal(t, utils.CodeBadRequest, decodeShareResponse(t, w).Code, query)
+		}
+	})
+}
+
+func TestFileTreeHandler_CreateFolder(t *testing.T) {
+	service := new(MockTreeService)
+	service.On("CreateFolder", mock.Anything, uint(7), (*uint)(nil), "docs").
+		Return(nil, pkgErrors.WrapError(pkgErrors.ErrResourceExists, "目标文件夹中已存在同名文件"))
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/files/folders", strings.NewReader(`{"name":"docs"}`))
+	setupFileTreeRouter(service).ServeHTTP(w, req)
+
+	assert.Equal(t, utils.CodeConflict, decodeShareResponse(t, w).Code)
+}
+
+// stubFileAuthorizer 按文件和权限返回文件所有者，没有授权时返回用户本身
+type stubFileAuthorizer struct {
+	owners map[uint]map[string]uint
+}
+
+func (a *stubFileAuthorizer) ResolveActingUser(_ context.Context, userID, fileID uint, permission string) (uint, error) {
+	if owner, ok := a.owners[fileID][permission]; ok {
+		return owner, nil
+	}
+	return userID, nil
+}
+
+func TestFileTreeHandler_Grants(t *testing.T) {
+	// 用户7对用户1的文件3和文件夹5有write权限，对文件夹6只有read权限
+	authorizer := &stubFileAuthorizer{owners: map[uint]map[string]uint{
+		3: {models.FilePermissionRead: 1, models.FilePermissionWrite: 1},
+		5: {models.FilePermissionRead: 1, models.FilePermissionWrite: 1},
+		6: {models.FilePermissionRead: 1},
+	}}
+	setup := func(service *MockTreeService) *gin.Engine {
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		handler := NewFileTreeHandler(service, zap.NewNop())
+		handler.SetFileAuthorizer(authorizer)
+		authed := router.Group("", func(c *gin.Context) {
+			c.Set("user_id", uint64(7))
+		})
+		authed.POST("/files/folders", handler.CreateFolder)
+		authed.POST("/files/:id/move", handler.MoveFile)
+		return router
+	}
+	serve := func(service *MockTreeService, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		setup(service).ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+		return w
+	}
+
+	t.Run("move within owner", func(t *testing.T) {
+		service := new(MockTreeService)
+		service.On("Move", mock.Anything, uint(1), uint(3), mock.Anything).Return(&models.File{Name: "a.txt"}, nil)
+
+		w := serve(service, "/files/3/move", `{"parent_id":5}`)
+		require.Equal(t, http.StatusOK, w.Code)
+		service.AssertExpectations(t)
+	})
+
+	t.Run("move without write on target", func(t *testing.T) {
+		w := serve(new(MockTreeService), "/files/3/move", `{"parent_id":6}`)
+		assert.Equal(t, utils.CodeForbidden, decodeShareResponse(t, w).Code)
+	})
+
+	t.Run("move to own root", func(t *testing.T) {
+		w := serve(new(MockTreeService), "/files/3/move", `{}`)
+		assert.Equal(t, utils.CodeForbidden, decodeShareResponse(t, w).Code)
+	})
+
+	t.Run("create folder in granted folder", func(t *testing.T) {
+		service := new(MockTreeService)
+		service.On("CreateFolder", mock.Anything, uint(1), mock.Anything, "新建").Return(&models.File{Name: "新建"}, nil)
+
+		w := serve(service, "/files/folders", `{"name":"新建","parent_id":5}`)
+		require.Equal(t, http.StatusOK, w.Code)
+		service.AssertExpectations(t)
+	})
+
+	t.Run("create folder with read only", func(t *testing.T) {
+		service := new(MockTreeService)
+		service.On("CreateFolder", mock.Anything, uint(7), mock.Anything, "新建").
+			Return(nil, pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, "无权访问该文件夹"))
+
+		w := serve(service, "/files/folders", `{"name":"新建","parent_id":6}`)
+		assert.Equal(t, utils.CodeForbidden, decodeShareResponse(t, w).Code)
+		service.AssertExpectations(t) // 没有授权时仍以当前用户调用，由文件服务拒绝
+	})
+}
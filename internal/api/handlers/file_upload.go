@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/service/automation"
+	"cloudpan/internal/service/file"
+	"cloudpan/internal/service/jobs"
+)
+
+// ChunkHashHeader 分片哈希请求头，算法为申请上传时协商的分片校验算法
+const ChunkHashHeader = "X-Chunk-Hash"
+
+// ChunkedUploadHandler 分片上传处理器
+type ChunkedUploadHandler struct {
+	service  file.ChunkedUploadService
+	scans    file.ScanScheduler
+	previews file.PreviewScheduler
+	rules    automation.UploadDispatcher
+	logger   *zap.Logger
+}
+
+// NewChunkedUploadHandler 创建分片上传处理器
+func NewChunkedUploadHandler(service file.ChunkedUploadService, logger *zap.Logger) *ChunkedUploadHandler {
+	return &ChunkedUploadHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// SetScanScheduler 设置病毒扫描任务调度，未设置时上传的文件一直处于待扫描状态
+func (h *ChunkedUploadHandler) SetScanScheduler(scans file.ScanScheduler) {
+	h.scans = scans
+}
+
+// SetPreviewScheduler 设置预览生成任务调度，未设置时上传完成后不生成预览
+func (h *ChunkedUploadHandler) SetPreviewScheduler(previews file.PreviewScheduler) {
+	h.previews = previews
+}
+
+// SetUploadDispatcher 设置文件夹规则触发，未设置时上传完成后不执行文件夹规则
+func (h *ChunkedUploadHandler) SetUploadDispatcher(rules automation.UploadDispatcher) {
+	h.rules = rules
+}
+
+// InitiateUpload 申请分片上传
+//
+// @Summary 申请分片上传
+// @Description 登记上传任务并返回分片规划(分片大小、总分片数、分片校验算法)。客户端按规划逐个上传分片，全部完成后调用合并接口。
+// @Description 上传有效期见expires_at，可能按客户端类型调整，上传过程中持续收到分片时自动延长；client_type为空时按User-Agent推断
+// @Tags 文件
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body file.ChunkedUploadRequest true "上传文件信息"
+// @Success 200 {object} utils.Response{data=file.ChunkedUploadSession} "上传任务"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "存储空间不足"
+// @Failure 404 {object} utils.Response "目标文件夹不存在"
+// @Router /api/v1/files/uploads [post]
+func (h *ChunkedUploadHandler) InitiateUpload(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	var req file.ChunkedUploadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+		return
+	}
+	req.ClientType = file.DetectUploadClient(req.ClientType, c.Request.UserAgent())
+
+	session, err := h.service.Initiate(c.Request.Context(), userID, &req)
+	if err != nil {
+		respondServiceError(c, err, "申请分片上传失败")
+		return
+	}
+
+	h.logger.Info("Chunked upload started",
+		zap.Uint("user_id", userID),
+		zap.String("upload_id", session.UploadID),
+		zap.Int64("size", session.Size),
+		zap.Int("total_chunks", session.TotalChunks),
+		zap.String("ip", c.ClientIP()))
+	utils.Success(c, session)
+}
+
+// UploadChunk 上传分片
+//
+// @Summary 上传分片
+// @Description 请求体为分片原始字节，X-Chunk-Hash 为分片哈希(十六进制)。分片大小和哈希校验失败时丢弃该分片，重传同一分片会覆盖
+// @Tags 文件
+// @Accept application/octet-stream
+// @Produce json
+// @Security BearerAuth
+// @Param upload_id path string true "上传任务ID"
+// @Param index path int true "分片索引(从0开始)"
+// @Param X-Chunk-Hash header string true "分片哈希"
+// @Success 200 {object} utils.Response{data=file.ChunkedUploadSession} "上传任务状态"
+// @Failure 400 {object} utils.Response "分片索引、大小或哈希校验失败"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "无权操作或上传任务已结束"
+// @Failure 404 {object} utils.Response "上传任务不存在"
+// @Router /api/v1/files/uploads/{upload_id}/chunks/{index} [put]
+func (h *ChunkedUploadHandler) UploadChunk(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	index, err := strconv.Atoi(c.Param("index"))
+	if err != nil || index < 0 {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "分片索引格式错误")
+		return
+	}
+	if c.Request.ContentLength < 0 {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "缺少分片大小(Content-Length)")
+		return
+	}
+
+	session, err := h.service.UploadChunk(c.Request.Context(), userID, c.Param("upload_id"), &file.ChunkUpload{
+		Index: index,
+		Hash:  c.GetHeader(ChunkHashHeader),
+		Size:  c.Request.ContentLength,
+		Data:  c.Request.Body,
+	})
+	if err != nil {
+		h.logger.Warn("Chunk upload rejected",
+			zap.Uint("user_id", userID),
+			zap.String("upload_id", c.Param("upload_id")),
+			zap.Int("index", index),
+			zap.String("ip", c.ClientIP()),
+			zap.Error(err))
+		respondServiceError(c, err, "上传分片失败")
+		return
+	}
+
+	utils.Success(c, session)
+}
+
+// GetUpload 查询上传任务
+//
+// @Summary 查询分片上传任务
+// @Description 返回分片规划和已接收的分片索引，客户端中断后据此补传缺失分片
+// @Tags 文件
+// @Produce json
+// @Security BearerAuth
+// @Param upload_id path string true "上传任务ID"
+// @Success 200 {object} utils.Response{data=file.ChunkedUploadSession} "上传任务状态"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "无权操作或上传任务已结束"
+// @Failure 404 {object} utils.Response "上传任务不存在"
+// @Router /api/v1/files/uploads/{upload_id} [get]
+func (h *ChunkedUploadHandler) GetUpload(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	session, err := h.service.GetSession(c.Request.Context(), userID, c.Param("upload_id"))
+	if err != nil {
+		respondServiceError(c, err, "查询上传任务失败")
+		return
+	}
+
+	utils.Success(c, session)
+}
+
+// AbortUpload 取消上传任务
+//
+// @Summary 取消分片上传
+// @Description 删除已上传的分片和文件夹中的上传占位文件，释放预留的存储空间。已合并或已失败的上传不能取消
+// @Tags 文件
+// @Produce json
+// @Security BearerAuth
+// @Param upload_id path string true "上传任务ID"
+// @Success 200 {object} utils.Response "取消成功"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "无权操作或上传任务已结束"
+// @Failure 404 {object} utils.Response "上传任务不存在"
+// @Router /api/v1/files/uploads/{upload_id} [delete]
+func (h *ChunkedUploadHandler) AbortUpload(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	uploadID := c.Param("upload_id")
+	if err := h.service.Abort(c.Request.Context(), userID, uploadID); err != nil {
+		respondServiceError(c, err, "取消上传失败")
+		return
+	}
+
+	h.logger.Info("Chunked upload aborted",
+		zap.Uint("user_id", userID),
+		zap.String("upload_id", uploadID),
+		zap.String("ip", c.ClientIP()))
+	utils.Success(c, nil)
+}
+
+// MergeUpload 合并分片
+//
+// @Summary 合并分片
+// @Description 全部分片上传完成后合并为最终文件，校验文件大小和哈希后激活。可安全重试，文件已激活时直接返回
+// @Description 病毒扫描和预览生成在后台进行，进度见返回文件的processing字段
+// @Tags 文件
+// @Produce json
+// @Security BearerAuth
+// @Param upload_id path string true "上传任务ID"
+// @Success 200 {object} utils.Response{data=models.File} "合并完成的文件"
+// @Failure 400 {object} utils.Response "分片不完整或文件哈希校验失败"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "无权操作、上传任务已结束或存储空间不足"
+// @Failure 404 {object} utils.Response "上传任务不存在"
+// @Router /api/v1/files/uploads/{upload_id}/merge [post]
+func (h *ChunkedUploadHandler) MergeUpload(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	merged, err := h.service.Merge(c.Request.Context(), userID, c.Param("upload_id"))
+	if err != nil {
+		respondServiceError(c, err, "合并分片失败")
+		return
+	}
+
+	h.logger.Info("Chunked upload completed",
+		zap.Uint("user_id", userID),
+		zap.Uint("file_id", merged.ID),
+		zap.Int64("size", merged.Size),
+		zap.String("ip", c.ClientIP()))
+	if h.scans != nil {
+		h.scans.Schedule(c.Request.Context(), merged)
+	}
+	if h.previews != nil {
+		h.previews.Schedule(c.Request.Context(), merged, jobs.PriorityInteractive)
+	}
+	if h.rules != nil {
+		h.rules.FileUploaded(merged)
+	}
+	utils.Success(c, merged)
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/service/file"
+)
+
+// uploadProgressHeartbeat SSE心跳间隔，防止代理因空闲断开连接
+const uploadProgressHeartbeat = 15 * time.Second
+
+// UploadProgressHandler 上传进度推送处理器
+type UploadProgressHandler struct {
+	hub    *file.ProgressHub
+	logger *zap.Logger
+}
+
+// NewUploadProgressHandler 创建上传进度推送处理器
+func NewUploadProgressHandler(hub *file.ProgressHub, logger *zap.Logger) *UploadProgressHandler {
+	return &UploadProgressHandler{
+		hub:    hub,
+		logger: logger,
+	}
+}
+
+// StreamUploadProgress 以SSE推送上传进度
+//
+// @Summary 订阅上传进度
+// @Description 以Server-Sent Events推送服务端观察到的上传进度，包括分片接收、合并和扫描阶段。
+// @Description 事件类型：progress（进度更新，data为UploadProgress）、heartbeat（心跳）。上传结束后连接自动关闭
+// @Tags 文件
+// @Produce text/event-stream
+// @Security BearerAuth
+// @Param upload_id path string true "上传任务ID"
+// @Success 200 {object} file.UploadProgress "进度事件流"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "无权访问"
+// @Failure 404 {object} utils.Response "上传任务不存在"
+// @Router /api/v1/files/uploads/{upload_id}/progress [get]
+func (h *UploadProgressHandler) StreamUploadProgress(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	uploadID := c.Param("upload_id")
+	events, cancel, err := h.hub.Subscribe(uploadID, userID)
+	if err != nil {
+		h.logger.Warn("Failed to subscribe upload progress",
+			zap.Uint("user_id", userID),
+			zap.String("upload_id", uploadID),
+			zap.Error(err),
+			zap.String("ip", c.ClientIP()))
+		respondServiceError(c, err, "订阅上传进度失败")
+		return
+	}
+	defer cancel()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // 禁用Nginx缓冲
+
+	heartbeat := time.NewTicker(uploadProgressHeartbeat)
+	defer heartbeat.Stop()
+
+	// 不使用c.Stream，它依赖已废弃的CloseNotifier；客户端断开通过请求上下文感知
+	for {
+		select {
+		case progress, ok := <-events:
+			if !ok {
+				return
+			}
+			c.SSEvent("progress", progress)
+			c.Writer.Flush()
+			if progress.IsFinished() {
+				return
+			}
+		case <-heartbeat.C:
+			c.SSEvent("heartbeat", time.Now().Unix())
+			c.Writer.Flush()
+		case <-c.Request.Context().Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/service/file"
+)
+
+func setupUploadProgressRouter(hub *file.ProgressHub, userID uint64) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler := NewUploadProgressHandler(hub, zap.NewNop())
+	router.GET("/uploads/:upload_id/progress", func(c *gin.Context) {
+		if userID > 0 {
+			c.Set("user_id", userID)
+		}
+		handler.StreamUploadProgress(c)
+	})
+	return router
+}
+
+func TestStreamUploadProgress_FinishedUploadClosesStream(t *testing.T) {
+	hub := file.NewProgressHub()
+	hub.Publish(file.UploadProgress{
+		UploadID:       "upload-1",
+		UserID:         7,
+		Phase:          file.UploadPhaseCompleted,
+		ReceivedChunks: 2,
+		TotalChunks:    2,
+	})
+
+	router := setupUploadProgressRouter(hub, 7)
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/uploads/upload-1/progress", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/event-stream")
+	body := w.Body.String()
+	assert.True(t, strings.Contains(body, "event:progress"))
+	assert.True(t, strings.Contains(body, `"phase":"completed"`))
+}
+
+func TestStreamUploadProgress_Errors(t *testing.T) {
+	hub := file.NewProgressHub()
+	hub.Publish(file.UploadProgress{UploadID: "upload-1", UserID: 7, Phase: file.UploadPhaseUploading})
+
+	tests := []struct {
+		name     string
+		userID   uint64
+		uploadID string
+		status   int
+	}{
+		{"unauthenticated", 0, "upload-1", http.StatusUnauthorized},
+		{"other user", 8, "upload-1", http.StatusForbidden},
+		{"unknown upload", 7, "missing", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := setupUploadProgressRouter(hub, tt.userID)
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/uploads/"+tt.uploadID+"/progress", nil)
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+}
@@ -0,0 +1,268 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+	"cloudpan/internal/service/file"
+	"cloudpan/internal/service/jobs"
+)
+
+// MockScanScheduler 模拟病毒扫描任务调度
+type MockScanScheduler struct {
+	mock.Mock
+}
+
+func (m *MockScanScheduler) Schedule(ctx context.Context, f *models.File) {
+	m.Called(ctx, f)
+}
+
+// MockChunkedUploadService 模拟分片上传服务
+type MockChunkedUploadService struct {
+	mock.Mock
+}
+
+func (m *MockChunkedUploadService) Initiate(ctx context.Context, userID uint, req *file.ChunkedUploadRequest) (*file.ChunkedUploadSession, error) {
+	args := m.Called(ctx, userID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*file.ChunkedUploadSession), args.Error(1)
+}
+
+func (m *MockChunkedUploadService) UploadChunk(ctx context.Context, userID uint, uploadID string, chunk *file.ChunkUpload) (*file.ChunkedUploadSession, error) {
+	args := m.Called(ctx, userID, uploadID, chunk)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*file.ChunkedUploadSession), args.Error(1)
+}
+
+func (m *MockChunkedUploadService) GetSession(ctx context.Context, userID uint, uploadID string) (*file.ChunkedUploadSession, error) {
+	args := m.Called(ctx, userID, uploadID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*file.ChunkedUploadSession), args.Error(1)
+}
+
+func (m *MockChunkedUploadService) Merge(ctx context.Context, userID uint, uploadID string) (*models.File, error) {
+	args := m.Called(ctx, userID, uploadID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.File), args.Error(1)
+}
+
+func (m *MockChunkedUploadService) Abort(ctx context.Context, userID uint, uploadID string) error {
+	args := m.Called(ctx, userID, uploadID)
+	return args.Error(0)
+}
+
+func (m *MockChunkedUploadService) AttachProgress(ctx context.Context, files []*models.File) error {
+	args := m.Called(ctx, files)
+	return args.Error(0)
+}
+
+func (m *MockChunkedUploadService) CleanupExpired(ctx context.Context) (int, error) {
+	args := m.Called(ctx)
+	return args.Int(0), args.Error(1)
+}
+
+func setupChunkedUploadRouter(service *MockChunkedUploadService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler := NewChunkedUploadHandler(service, zap.NewNop())
+	authed := router.Group("", func(c *gin.Context) {
+		c.Set("user_id", uint64(7))
+	})
+	authed.POST("/files/uploads", handler.InitiateUpload)
+	authed.GET("/files/uploads/:upload_id", handler.GetUpload)
+	authed.DELETE("/files/uploads/:upload_id", handler.AbortUpload)
+	authed.PUT("/files/uploads/:upload_id/chunks/:index", handler.UploadChunk)
+	authed.POST("/files/uploads/:upload_id/merge", handler.MergeUpload)
+	return router
+}
+
+func TestChunkedUploadHandler_Initiate(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		service := new(MockChunkedUploadService)
+		service.On("Initiate", mock.Anything, uint(7), mock.MatchedBy(func(req *file.ChunkedUploadRequest) bool {
+			return req.Name == "disk.iso" && req.Size == 12 && req.Hash == "abc"
+		})).Return(&file.ChunkedUploadSession{UploadID: "up-1", TotalChunks: 3}, nil)
+
+		body := `{"name":"disk.iso","size":12,"hash":"abc"}`
+		w := httptest.NewRecorder()
+		setupChunkedUploadRouter(service).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/files/uploads", strings.NewReader(body)))
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, utils.CodeSuccess, decodeShareResponse(t, w).Code)
+		service.AssertExpectations(t)
+	})
+
+	t.Run("client type from user agent", func(t *testing.T) {
+		service := new(MockChunkedUploadService)
+		service.On("Initiate", mock.Anything, uint(7), mock.MatchedBy(func(req *file.ChunkedUploadRequest) bool {
+			return req.ClientType == file.UploadClientMobile
+		})).Return(&file.ChunkedUploadSession{UploadID: "up-1", TotalChunks: 3}, nil)
+
+		req := httptest.NewRequest(http.MethodPost, "/files/uploads", strings.NewReader(`{"name":"disk.iso","size":12,"hash":"abc"}`))
+		req.Header.Set("User-Agent", "Mozilla/5.0 (Linux; Android 14) Mobile Safari/537.36")
+		w := httptest.NewRecorder()
+		setupChunkedUploadRouter(service).ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		service.AssertExpectations(t)
+	})
+
+	t.Run("missing fields", func(t *testing.T) {
+		service := new(MockChunkedUploadService)
+		w := httptest.NewRecorder()
+		setupChunkedUploadRouter(service).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/files/uploads", strings.NewReader(`{"name":"disk.iso"}`)))
+
+		assert.Equal(t, utils.CodeBadRequest, decodeShareResponse(t, w).Code)
+		service.AssertNotCalled(t, "Initiate", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestChunkedUploadHandler_UploadChunk(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		service := new(MockChunkedUploadService)
+		service.On("UploadChunk", mock.Anything, uint(7), "up-1", mock.MatchedBy(func(chunk *file.ChunkUpload) bool {
+			data, _ := io.ReadAll(chunk.Data)
+			return chunk.Index == 2 && chunk.Hash == "c0ffee" && chunk.Size == 4 && string(data) == "data"
+		})).Return(&file.ChunkedUploadSession{UploadID: "up-1", UploadedChunks: []int{2}}, nil)
+
+		req := httptest.NewRequest(http.MethodPut, "/files/uploads/up-1/chunks/2", strings.NewReader("data"))
+		req.Header.Set(ChunkHashHeader, "c0ffee")
+		w := httptest.NewRecorder()
+		setupChunkedUploadRouter(service).ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		service.AssertExpectations(t)
+	})
+
+	t.Run("invalid index", func(t *testing.T) {
+		service := new(MockChunkedUploadService)
+		w := httptest.NewRecorder()
+		setupChunkedUploadRouter(service).ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/files/uploads/up-1/chunks/x", strings.NewReader("data")))
+
+		assert.Equal(t, utils.CodeBadRequest, decodeShareResponse(t, w).Code)
+		service.AssertNotCalled(t, "UploadChunk", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("hash mismatch", func(t *testing.T) {
+		service := new(MockChunkedUploadService)
+		service.On("UploadChunk", mock.Anything, uint(7), "up-1", mock.Anything).
+			Return(nil, pkgErrors.WrapError(pkgErrors.ErrValidationFailed, "分片校验失败"))
+
+		req := httptest.NewRequest(http.MethodPut, "/files/uploads/up-1/chunks/0", strings.NewReader("data"))
+		req.Header.Set(ChunkHashHeader, "bad")
+		w := httptest.NewRecorder()
+		setupChunkedUploadRouter(service).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, utils.CodeValidationError, decodeShareResponse(t, w).Code)
+	})
+}
+
+func TestChunkedUploadHandler_GetUpload(t *testing.T) {
+	service := new(MockChunkedUploadService)
+	service.On("GetSession", mock.Anything, uint(7), "missing").
+		Return(nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "上传任务不存在"))
+
+	w := httptest.NewRecorder()
+	setupChunkedUploadRouter(service).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/files/uploads/missing", nil))
+
+	assert.Equal(t, utils.CodeNotFound, decodeShareResponse(t, w).Code)
+}
+
+func TestChunkedUploadHandler_Abort(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		service := new(MockChunkedUploadService)
+		service.On("Abort", mock.Anything, uint(7), "up-1").Return(nil)
+
+		w := httptest.NewRecorder()
+		setupChunkedUploadRouter(service).ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/files/uploads/up-1", nil))
+
+		require.Equal(t, http.StatusOK, w.Code)
+		service.AssertExpectations(t)
+	})
+
+	t.Run("already finished", func(t *testing.T) {
+		service := new(MockChunkedUploadService)
+		service.On("Abort", mock.Anything, uint(7), "up-1").
+			Return(pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "上传任务已结束"))
+
+		w := httptest.NewRecorder()
+		setupChunkedUploadRouter(service).ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/files/uploads/up-1", nil))
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+}
+
+func TestChunkedUploadHandler_Merge(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		service := new(MockChunkedUploadService)
+		merged := &models.File{Name: "disk.iso", Size: 12, Status: "active"}
+		merged.ID = 42
+		service.On("Merge", mock.Anything, uint(7), "up-1").Return(merged, nil)
+
+		w := httptest.NewRecorder()
+		setupChunkedUploadRouter(service).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/files/uploads/up-1/merge", nil))
+
+		require.Equal(t, http.StatusOK, w.Code)
+		service.AssertExpectations(t)
+	})
+
+	t.Run("schedules scan and preview", func(t *testing.T) {
+		service := new(MockChunkedUploadService)
+		merged := &models.File{Name: "photo.png", Size: 12, Status: "active"}
+		merged.ID = 42
+		service.On("Merge", mock.Anything, uint(7), "up-1").Return(merged, nil)
+		scans := new(MockScanScheduler)
+		scans.On("Schedule", mock.Anything, merged).Return()
+		previews := new(MockFilePreviewService)
+		previews.On("Schedule", mock.Anything, merged, jobs.PriorityInteractive).Return()
+
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		handler := NewChunkedUploadHandler(service, zap.NewNop())
+		handler.SetScanScheduler(scans)
+		handler.SetPreviewScheduler(previews)
+		router.POST("/files/uploads/:upload_id/merge", func(c *gin.Context) {
+			c.Set("user_id", uint64(7))
+		}, handler.MergeUpload)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/files/uploads/up-1/merge", nil))
+
+		require.Equal(t, http.StatusOK, w.Code)
+		scans.AssertExpectations(t)
+		previews.AssertExpectations(t)
+	})
+
+	t.Run("incomplete", func(t *testing.T) {
+		service := new(MockChunkedUploadService)
+		service.On("Merge", mock.Anything, uint(7), "up-1").
+			Return(nil, pkgErrors.WrapError(pkgErrors.ErrValidationFailed, "分片不完整: 已上传 1/3"))
+
+		w := httptest.NewRecorder()
+		setupChunkedUploadRouter(service).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/files/uploads/up-1/merge", nil))
+
+		assert.Equal(t, utils.CodeValidationError, decodeShareResponse(t, w).Code)
+	})
+}
@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+	"cloudpan/internal/service/file"
+	"cloudpan/internal/service/jobs"
+)
+
+// ChangeLogHeader 覆盖上传时原内容的变更说明请求头
+const ChangeLogHeader = "X-Change-Log"
+
+// FileVersionHandler 文件历史版本处理器
+type FileVersionHandler struct {
+	fileAccess
+	service  file.VersionService
+	scans    file.ScanScheduler
+	previews file.PreviewScheduler
+	logger   *zap.Logger
+}
+
+// NewFileVersionHandler 创建文件历史版本处理器
+func NewFileVersionHandler(service file.VersionService, logger *zap.Logger) *FileVersionHandler {
+	return &FileVersionHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// SetScanScheduler 设置病毒扫描任务调度，未设置时替换内容后的文件一直处于待扫描状态
+func (h *FileVersionHandler) SetScanScheduler(scans file.ScanScheduler) {
+	h.scans = scans
+}
+
+// SetPreviewScheduler 设置预览生成任务调度，未设置时替换内容后不重新生成预览
+func (h *FileVersionHandler) SetPreviewScheduler(previews file.PreviewScheduler) {
+	h.previews = previews
+}
+
+// OverwriteContent 覆盖文件内容
+//
+// @Summary 覆盖文件内容
+// @Description 请求体为新内容的原始字节，原内容自动保存为历史版本，超出保留个数的最早版本被删除并释放存储空间。
+// @Description 新内容占用文件所有者的存储空间；Content-Type 为新内容的类型，未提供或为application/octet-stream时保持不变。
+// @Description 获得write授权的用户可以覆盖他人的文件
+// @Tags 文件
+// @Accept application/octet-stream
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "文件ID"
+// @Param X-Change-Log header string false "原内容的变更说明，最多500个字符"
+// @Success 200 {object} utils.Response{data=models.File} "覆盖后的文件"
+// @Failure 400 {object} utils.Response "请求参数错误或文件大小超出限制"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "无权修改该文件、文件当前不可操作或存储空间不足"
+// @Failure 404 {object} utils.Response "文件不存在"
+// @Failure 409 {object} utils.Response "文件内容已被修改"
+// @Router /api/v1/files/{id}/content [put]
+func (h *FileVersionHandler) OverwriteContent(c *gin.Context) {
+	userID, fileID, ok := h.fileParams(c, models.FilePermissionWrite)
+	if !ok {
+		return
+	}
+	if c.Request.ContentLength < 0 {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "缺少文件大小(Content-Length)")
+		return
+	}
+
+	mimeType := c.ContentType()
+	if mimeType == "application/octet-stream" {
+		mimeType = ""
+	}
+	updated, err := h.service.Overwrite(c.Request.Context(), userID, fileID, &file.OverwriteRequest{
+		Content:   c.Request.Body,
+		Size:      c.Request.ContentLength,
+		MimeType:  mimeType,
+		ChangeLog: c.GetHeader(ChangeLogHeader),
+	})
+	if err != nil {
+		h.logger.Warn("File overwrite rejected",
+			zap.Uint("user_id", userID),
+			zap.Uint("file_id", fileID),
+			zap.String("ip", c.ClientIP()),
+			zap.Error(err))
+		respondServiceError(c, err, "覆盖文件失败")
+		return
+	}
+
+	h.contentReplaced(c, updated)
+	utils.Success(c, updated)
+}
+
+// ListVersions 列出文件的历史版本
+//
+// @Summary 列出文件历史版本
+// @Description 返回文件的历史版本，按版本号降序排列。获得read授权的用户可以查看他人文件的历史版本
+// @Tags 文件
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "文件ID"
+// @Success 200 {object} utils.Response{data=[]file.FileVersion} "历史版本列表"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "无权访问该文件或文件当前不可操作"
+// @Failure 404 {object} utils.Response "文件不存在"
+// @Router /api/v1/files/{id}/versions [get]
+func (h *FileVersionHandler) ListVersions(c *gin.Context) {
+	userID, fileID, ok := h.fileParams(c, models.FilePermissionRead)
+	if !ok {
+		return
+	}
+
+	versions, err := h.service.ListVersions(c.Request.Context(), userID, fileID)
+	if err != nil {
+		respondServiceError(c, err, "获取历史版本失败")
+		return
+	}
+	utils.Success(c, versions)
+}
+
+// DownloadVersion 下载历史版本
+//
+// @Summary 下载文件历史版本
+// @Description 返回历史版本的内容，文件名为登记该版本时的文件名，支持Range断点续传。获得read授权的用户可以下载他人文件的历史版本
+// @Tags 文件
+// @Produce octet-stream
+// @Security BearerAuth
+// @Param id path int true "文件ID"
+// @Param number path int true "版本号"
+// @Param Range header string false "字节范围，如bytes=0-1023"
+// @Success 200 {file} binary "版本内容"
+// @Success 206 {file} binary "部分内容"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "无权访问该文件或文件当前不可操作"
+// @Failure 404 {object} utils.Response "文件或版本不存在"
+// @Router /api/v1/files/{id}/versions/{number}/download [get]
+func (h *FileVersionHandler) DownloadVersion(c *gin.Context) {
+	userID, fileID, ok := h.fileParams(c, models.FilePermissionRead)
+	if !ok {
+		return
+	}
+	number, ok := parseVersionNumber(c)
+	if !ok {
+		return
+	}
+
+	download, err := h.service.OpenVersion(c.Request.Context(), userID, fileID, number)
+	if err != nil {
+		respondServiceError(c, err, "下载历史版本失败")
+		return
+	}
+	defer download.Content.Close()
+
+	c.Header("Content-Type", download.MimeType)
+	c.Header("Content-Disposition", contentDisposition(download.Name))
+	c.Header("X-Content-Type-Options", "nosniff")
+	if download.ETag != "" {
+		c.Header("ETag", download.ETag)
+	}
+	http.ServeContent(c.Writer, c.Request, download.Name, download.ModTime, download.Content)
+}
+
+// RestoreVersion 恢复历史版本
+//
+// @Summary 恢复文件历史版本
+// @Description 将历史版本恢复为文件的当前内容，当前内容保存为新的历史版本，被恢复的版本从历史中移除，不额外占用存储空间。
+// @Description 获得write授权的用户可以恢复他人文件的历史版本
+// @Tags 文件
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "文件ID"
+// @Param number path int true "版本号"
+// @Success 200 {object} utils.Response{data=models.File} "恢复后的文件"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "无权修改该文件或文件当前不可操作"
+// @Failure 404 {object} utils.Response "文件或版本不存在"
+// @Failure 409 {object} utils.Response "文件内容已被修改"
+// @Router /api/v1/files/{id}/versions/{number}/restore [post]
+func (h *FileVersionHandler) RestoreVersion(c *gin.Context) {
+	userID, fileID, ok := h.fileParams(c, models.FilePermissionWrite)
+	if !ok {
+		return
+	}
+	number, ok := parseVersionNumber(c)
+	if !ok {
+		return
+	}
+
+	restored, err := h.service.RestoreVersion(c.Request.Context(), userID, fileID, number)
+	if err != nil {
+		respondServiceError(c, err, "恢复历史版本失败")
+		return
+	}
+
+	h.contentReplaced(c, restored)
+	utils.Success(c, restored)
+}
+
+// contentReplaced 文件内容替换后重新安排病毒扫描和预览生成
+func (h *FileVersionHandler) contentReplaced(c *gin.Context, updated *models.File) {
+	if h.scans != nil {
+		h.scans.Schedule(c.Request.Context(), updated)
+	}
+	if h.previews != nil {
+		h.previews.Schedule(c.Request.Context(), updated, jobs.PriorityInteractive)
+	}
+}
+
+// fileParams 解析文件ID并检查权限，返回调用服务时使用的用户ID，失败时已写入响应
+func (h *FileVersionHandler) fileParams(c *gin.Context, permission string) (uint, uint, bool) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return 0, 0, false
+	}
+	fileID, ok := parseIDParam(c, "id")
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "文件ID格式错误")
+		return 0, 0, false
+	}
+	actingUserID, ok := h.actingUser(c, userID, fileID, permission)
+	if !ok {
+		return 0, 0, false
+	}
+	return actingUserID, fileID, true
+}
+
+// parseVersionNumber 解析版本号，失败时已写入响应
+func parseVersionNumber(c *gin.Context) (int, bool) {
+	number, err := strconv.Atoi(c.Param("number"))
+	if err != nil || number <= 0 {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "版本号格式错误")
+		return 0, false
+	}
+	return number, true
+}
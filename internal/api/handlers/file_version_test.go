@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+	"cloudpan/internal/service/file"
+)
+
+// MockVersionService 模拟文件历史版本服务
+type MockVersionService struct {
+	mock.Mock
+}
+
+func (m *MockVersionService) Overwrite(ctx context.Context, userID, fileID uint, req *file.OverwriteRequest) (*models.File, error) {
+	args := m.Called(ctx, userID, fileID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.File), args.Error(1)
+}
+
+func (m *MockVersionService) ListVersions(ctx context.Context, userID, fileID uint) ([]*file.FileVersion, error) {
+	args := m.Called(ctx, userID, fileID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*file.FileVersion), args.Error(1)
+}
+
+func (m *MockVersionService) OpenVersion(ctx context.Context, userID, fileID uint, number int) (*file.FileDownload, error) {
+	args := m.Called(ctx, userID, fileID, number)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*file.FileDownload), args.Error(1)
+}
+
+func (m *MockVersionService) RestoreVersion(ctx context.Context, userID, fileID uint, number int) (*models.File, error) {
+	args := m.Called(ctx, userID, fileID, number)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.File), args.Error(1)
+}
+
+func (m *MockVersionService) PurgeVersions(ctx context.Context, fileIDs []uint) (int64, error) {
+	args := m.Called(ctx, fileIDs)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func setupFileVersionRouter(service *MockVersionService, authorizer FileAuthorizer) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler := NewFileVersionHandler(service, zap.NewNop())
+	if authorizer != nil {
+		handler.SetFileAuthorizer(authorizer)
+	}
+	authed := router.Group("", func(c *gin.Context) {
+		c.Set("user_id", uint64(7))
+	})
+	authed.PUT("/files/:id/content", handler.OverwriteContent)
+	authed.GET("/files/:id/versions", handler.ListVersions)
+	authed.GET("/files/:id/versions/:number/download", handler.DownloadVersion)
+	authed.POST("/files/:id/versions/:number/restore", handler.RestoreVersion)
+	return router
+}
+
+func TestFileVersionHandler_Overwrite(t *testing.T) {
+	service := new(MockVersionService)
+	service.On("Overwrite", mock.Anything, uint(7), uint(5), mock.MatchedBy(func(req *file.OverwriteRequest) bool {
+		data, _ := io.ReadAll(req.Content)
+		return string(data) == "hello" && req.Size == 5 && req.MimeType == "text/plain" && req.ChangeLog == "typo"
+	})).Return(&models.File{Name: "a.txt"}, nil)
+	router := setupFileVersionRouter(service, nil)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, "/files/5/content", strings.NewReader("hello"))
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	req.Header.Set(ChangeLogHeader, "typo")
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"name":"a.txt"`)
+	service.AssertExpectations(t)
+}
+
+func TestFileVersionHandler_OverwriteErrors(t *testing.T) {
+	service := new(MockVersionService)
+	service.On("Overwrite", mock.Anything, uint(7), uint(5), mock.Anything).
+		Return(nil, pkgErrors.WrapError(pkgErrors.ErrResourceExists, "文件内容已被修改，请刷新后重试")).Once()
+	service.On("Overwrite", mock.Anything, uint(7), uint(5), mock.Anything).
+		Return(nil, pkgErrors.WrapError(pkgErrors.ErrQuotaExceeded, "存储空间不足")).Once()
+	router := setupFileVersionRouter(service, nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/files/5/content", strings.NewReader("x")))
+	assert.Equal(t, utils.CodeConflict, decodeShareResponse(t, w).Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/files/5/content", strings.NewReader("x")))
+	assert.Equal(t, utils.CodeQuotaExceeded, decodeShareResponse(t, w).Code)
+
+	// 缺少Content-Length
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, "/files/5/content", strings.NewReader("x"))
+	req.ContentLength = -1
+	router.ServeHTTP(w, req)
+	assert.Equal(t, utils.CodeBadRequest, decodeShareResponse(t, w).Code)
+	service.AssertExpectations(t)
+}
+
+func TestFileVersionHandler_ListAndDownload(t *testing.T) {
+	content := "old content"
+	service := new(MockVersionService)
+	service.On("ListVersions", mock.Anything, uint(7), uint(5)).
+		Return([]*file.FileVersion{{VersionNumber: 2, Name: "a.txt", Size: 11}}, nil)
+	service.On("OpenVersion", mock.Anything, uint(7), uint(5), 2).Return(&file.FileDownload{
+		FileID:   5,
+		Name:     "a.txt",
+		MimeType: "text/plain",
+		Size:     int64(len(content)),
+		ModTime:  time.Now(),
+		Content:  nopSeekCloser{strings.NewReader(content)},
+	}, nil)
+	service.On("OpenVersion", mock.Anything, uint(7), uint(5), 9).
+		Return(nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "版本不存在"))
+	router := setupFileVersionRouter(service, nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/files/5/versions", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"version_number":2`)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/files/5/versions/2/download", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, content, w.Body.String())
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "a.txt")
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/files/5/versions/9/download", nil))
+	assert.Equal(t, utils.CodeNotFound, decodeShareResponse(t, w).Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/files/5/versions/0/download", nil))
+	assert.Equal(t, utils.CodeBadRequest, decodeShareResponse(t, w).Code)
+	service.AssertExpectations(t)
+}
+
+func TestFileVersionHandler_RestoreGrants(t *testing.T) {
+	// 用户7对用户3的文件5有write权限，对文件6只有read权限
+	authorizer := &stubFileAuthorizer{owners: map[uint]map[string]uint{
+		5: {models.FilePermissionRead: 3, models.FilePermissionWrite: 3},
+		6: {models.FilePermissionRead: 3},
+	}}
+	service := new(MockVersionService)
+	service.On("RestoreVersion", mock.Anything, uint(3), uint(5), 1).Return(&models.File{Name: "a.txt"}, nil)
+	service.On("RestoreVersion", mock.Anything, uint(7), uint(6), 1).
+		Return(nil, pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, "无权访问该文件"))
+	router := setupFileVersionRouter(service, authorizer)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/files/5/versions/1/restore", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/files/6/versions/1/restore", nil))
+	assert.Equal(t, utils.CodeForbidden, decodeShareResponse(t, w).Code)
+	service.AssertExpectations(t)
+}
@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/service/automation"
+)
+
+// 规则执行日志分页参数
+const (
+	defaultRuleLogPageSize = 20
+	maxRuleLogPageSize     = 100
+)
+
+// FolderRuleHandler 文件夹自动化规则处理器
+type FolderRuleHandler struct {
+	service automation.RuleService
+	logger  *zap.Logger
+}
+
+// NewFolderRuleHandler 创建文件夹自动化规则处理器
+func NewFolderRuleHandler(service automation.RuleService, logger *zap.Logger) *FolderRuleHandler {
+	return &FolderRuleHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// ListRules 列出文件夹规则
+//
+// @Summary 文件夹规则列表
+// @Description 列出当前用户的文件夹自动化规则，按创建顺序排列；指定folder_id时只列出该文件夹上的规则
+// @Tags 文件夹规则
+// @Produce json
+// @Security BearerAuth
+// @Param folder_id query int false "文件夹ID"
+// @Success 200 {object} utils.Response{data=[]models.FolderRule} "规则列表"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Router /api/v1/folder-rules [get]
+func (h *FolderRuleHandler) ListRules(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	var folderID *uint
+	if value := c.Query("folder_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 64)
+		if err != nil || id == 0 {
+			utils.ErrorWithMessage(c, utils.CodeBadRequest, "文件夹ID格式错误")
+			return
+		}
+		folder := uint(id)
+		folderID = &folder
+	}
+
+	rules, err := h.service.ListRules(c.Request.Context(), userID, folderID)
+	if err != nil {
+		respondServiceError(c, err, "获取规则列表失败")
+		return
+	}
+
+	utils.Success(c, rules)
+}
+
+// CreateRule 创建文件夹规则
+//
+// @Summary 创建文件夹规则
+// @Description 文件上传到该文件夹(include_subfolders为true时包括子文件夹)且满足条件时异步执行动作：
+// @Description webhook向webhook_url发送POST请求(设置webhook_secret时带X-Cloudpan-Signature签名头)；tag为文件添加标签；move将文件移动到target_folder_id(为空表示根目录)。
+// @Description 条件表达式示例：ext in ("jpg", "png") and size > 5MB，可用字段为name、ext、mime、path、size、tags，为空时总是执行
+// @Tags 文件夹规则
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body automation.RuleRequest true "规则内容"
+// @Success 200 {object} utils.Response{data=models.FolderRule} "创建的规则"
+// @Failure 400 {object} utils.Response "请求参数错误、条件表达式无效或动作参数不合法"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "无权访问文件夹或规则数已达上限"
+// @Failure 404 {object} utils.Response "文件夹不存在"
+// @Router /api/v1/folder-rules [post]
+func (h *FolderRuleHandler) CreateRule(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	var req automation.RuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+		return
+	}
+
+	rule, err := h.service.CreateRule(c.Request.Context(), userID, &req)
+	if err != nil {
+		respondServiceError(c, err, "创建规则失败")
+		return
+	}
+
+	utils.Success(c, rule)
+}
+
+// UpdateRule 更新文件夹规则
+//
+// @Summary 更新文件夹规则
+// @Description 只更新请求中出现的字段，动作类型不可修改。webhook_secret为空字符串时取消签名；move_to_root为true时改为移动到根目录
+// @Tags 文件夹规则
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "规则ID"
+// @Param request body automation.RuleUpdate true "更新内容"
+// @Success 200 {object} utils.Response{data=models.FolderRule} "更新后的规则"
+// @Failure 400 {object} utils.Response "请求参数错误、条件表达式无效或动作参数不合法"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "无权访问目标文件夹"
+// @Failure 404 {object} utils.Response "规则不存在"
+// @Router /api/v1/folder-rules/{id} [patch]
+func (h *FolderRuleHandler) UpdateRule(c *gin.Context) {
+	userID, ruleID, ok := h.parseTarget(c)
+	if !ok {
+		return
+	}
+
+	var req automation.RuleUpdate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+		return
+	}
+
+	rule, err := h.service.UpdateRule(c.Request.Context(), userID, ruleID, &req)
+	if err != nil {
+		respondServiceError(c, err, "更新规则失败")
+		return
+	}
+
+	utils.Success(c, rule)
+}
+
+// DeleteRule 删除文件夹规则
+//
+// @Summary 删除文件夹规则
+// @Description 删除规则，已提交的执行任务不再执行该规则
+// @Tags 文件夹规则
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "规则ID"
+// @Success 200 {object} utils.Response "删除成功"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 404 {object} utils.Response "规则不存在"
+// @Router /api/v1/folder-rules/{id} [delete]
+func (h *FolderRuleHandler) DeleteRule(c *gin.Context) {
+	userID, ruleID, ok := h.parseTarget(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteRule(c.Request.Context(), userID, ruleID); err != nil {
+		respondServiceError(c, err, "删除规则失败")
+		return
+	}
+
+	utils.Success(c, nil)
+}
+
+// ListRuleLogs 列出规则执行日志
+//
+// @Summary 规则执行日志
+// @Description 分页列出规则的执行日志，按执行时间倒序。只记录条件匹配后执行过动作的文件
+// @Tags 文件夹规则
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "规则ID"
+// @Param page query int false "页码" default(1)
+// @Param page_size query int false "每页数量(最大100)" default(20)
+// @Success 200 {object} utils.ListResponse{data=[]models.FolderRuleLog} "执行日志"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 404 {object} utils.Response "规则不存在"
+// @Router /api/v1/folder-rules/{id}/logs [get]
+func (h *FolderRuleHandler) ListRuleLogs(c *gin.Context) {
+	userID, ruleID, ok := h.parseTarget(c)
+	if !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(c.Query("page"))
+	if page < 1 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(c.Query("page_size"))
+	if pageSize < 1 {
+		pageSize = defaultRuleLogPageSize
+	}
+	if pageSize > maxRuleLogPageSize {
+		pageSize = maxRuleLogPageSize
+	}
+
+	logs, total, err := h.service.ListLogs(c.Request.Context(), userID, ruleID, page, pageSize)
+	if err != nil {
+		respondServiceError(c, err, "获取执行日志失败")
+		return
+	}
+
+	utils.SuccessList(c, logs, utils.NewPagination(page, pageSize, total))
+}
+
+// parseTarget 解析当前用户和路径中的规则ID，失败时已写入响应
+func (h *FolderRuleHandler) parseTarget(c *gin.Context) (uint, uint, bool) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return 0, 0, false
+	}
+	ruleID, ok := parseIDParam(c, "id")
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "规则ID格式错误")
+		return 0, 0, false
+	}
+	return userID, ruleID, true
+}
@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+	"cloudpan/internal/service/automation"
+)
+
+// MockRuleService 模拟文件夹规则服务
+type MockRuleService struct {
+	mock.Mock
+}
+
+func (m *MockRuleService) CreateRule(ctx context.Context, userID uint, req *automation.RuleRequest) (*models.FolderRule, error) {
+	args := m.Called(ctx, userID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.FolderRule), args.Error(1)
+}
+
+func (m *MockRuleService) ListRules(ctx context.Context, userID uint, folderID *uint) ([]*models.FolderRule, error) {
+	args := m.Called(ctx, userID, folderID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.FolderRule), args.Error(1)
+}
+
+func (m *MockRuleService) UpdateRule(ctx context.Context, userID, ruleID uint, req *automation.RuleUpdate) (*models.FolderRule, error) {
+	args := m.Called(ctx, userID, ruleID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.FolderRule), args.Error(1)
+}
+
+func (m *MockRuleService) DeleteRule(ctx context.Context, userID, ruleID uint) error {
+	args := m.Called(ctx, userID, ruleID)
+	return args.Error(0)
+}
+
+func (m *MockRuleService) ListLogs(ctx context.Context, userID, ruleID uint, page, pageSize int) ([]*models.FolderRuleLog, int64, error) {
+	args := m.Called(ctx, userID, ruleID, page, pageSize)
+	if args.Get(0) == nil {
+		return nil, 0, args.Error(2)
+	}
+	return args.Get(0).([]*models.FolderRuleLog), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockRuleService) PurgeLogs(ctx context.Context, limit int) (int64, error) {
+	args := m.Called(ctx, limit)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func setupFolderRuleRouter(service *MockRuleService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler := NewFolderRuleHandler(service, zap.NewNop())
+	authed := router.Group("/folder-rules", func(c *gin.Context) {
+		c.Set("user_id", uint64(7))
+	})
+	authed.GET("", handler.ListRules)
+	authed.POST("", handler.CreateRule)
+	authed.PATCH("/:id", handler.UpdateRule)
+	authed.DELETE("/:id", handler.DeleteRule)
+	authed.GET("/:id/logs", handler.ListRuleLogs)
+	return router
+}
+
+func TestFolderRuleHandler_CreateRule(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		service := new(MockRuleService)
+		service.On("CreateRule", mock.Anything, uint(7), mock.MatchedBy(func(req *automation.RuleRequest) bool {
+			return req.FolderID == 3 && req.Action == models.RuleActionWebhook && req.WebhookSecret == "s3cret"
+		})).Return(&models.FolderRule{FolderID: 3, Action: models.RuleActionWebhook, WebhookSecret: "s3cret", WebhookSigned: true}, nil)
+
+		body := `{"folder_id":3,"name":"通知","condition":"ext == \"pdf\"","action":"webhook","webhook_url":"https://example.com/hook","webhook_secret":"s3cret"}`
+		w := httptest.NewRecorder()
+		setupFolderRuleRouter(service).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/folder-rules", strings.NewReader(body)))
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), "s3cret")
+		assert.Contains(t, w.Body.String(), `"webhook_signed":true`)
+		service.AssertExpectations(t)
+	})
+
+	t.Run("missing fields", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		setupFolderRuleRouter(new(MockRuleService)).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/folder-rules", strings.NewReader(`{"name":"x"}`)))
+
+		assert.Equal(t, utils.CodeBadRequest, decodeShareResponse(t, w).Code)
+	})
+
+	t.Run("invalid condition", func(t *testing.T) {
+		service := new(MockRuleService)
+		service.On("CreateRule", mock.Anything, uint(7), mock.Anything).
+			Return(nil, pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "条件表达式第5个字符处：表达式不完整"))
+
+		body := `{"folder_id":3,"name":"x","condition":"ext ==","action":"move"}`
+		w := httptest.NewRecorder()
+		setupFolderRuleRouter(service).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/folder-rules", strings.NewReader(body)))
+
+		resp := decodeShareResponse(t, w)
+		assert.Equal(t, utils.CodeValidationError, resp.Code)
+		assert.Contains(t, resp.Message, "表达式不完整")
+	})
+
+	t.Run("limit reached", func(t *testing.T) {
+		service := new(MockRuleService)
+		service.On("CreateRule", mock.Anything, uint(7), mock.Anything).
+			Return(nil, pkgErrors.WrapError(pkgErrors.ErrQuotaExceeded, "最多只能创建 50 条规则"))
+
+		body := `{"folder_id":3,"name":"x","action":"move"}`
+		w := httptest.NewRecorder()
+		setupFolderRuleRouter(service).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/folder-rules", strings.NewReader(body)))
+
+		assert.Equal(t, utils.CodeQuotaExceeded, decodeShareResponse(t, w).Code)
+	})
+}
+
+func TestFolderRuleHandler_ListRules(t *testing.T) {
+	t.Run("by folder", func(t *testing.T) {
+		service := new(MockRuleService)
+		service.On("ListRules", mock.Anything, uint(7), mock.MatchedBy(func(id *uint) bool { return id != nil && *id == 3 })).
+			Return([]*models.FolderRule{{FolderID: 3}}, nil)
+
+		w := httptest.NewRecorder()
+		setupFolderRuleRouter(service).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/folder-rules?folder_id=3", nil))
+
+		require.Equal(t, http.StatusOK, w.Code)
+		service.AssertExpectations(t)
+	})
+
+	t.Run("invalid folder id", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		setupFolderRuleRouter(new(MockRuleService)).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/folder-rules?folder_id=abc", nil))
+
+		assert.Equal(t, utils.CodeBadRequest, decodeShareResponse(t, w).Code)
+	})
+}
+
+func TestFolderRuleHandler_UpdateAndDelete(t *testing.T) {
+	t.Run("update", func(t *testing.T) {
+		service := new(MockRuleService)
+		service.On("UpdateRule", mock.Anything, uint(7), uint(5), mock.MatchedBy(func(req *automation.RuleUpdate) bool {
+			return req.IsActive != nil && !*req.IsActive && req.Name == nil
+		})).Return(&models.FolderRule{Name: "x"}, nil)
+
+		w := httptest.NewRecorder()
+		setupFolderRuleRouter(service).ServeHTTP(w, httptest.NewRequest(http.MethodPatch, "/folder-rules/5", strings.NewReader(`{"is_active":false}`)))
+
+		require.Equal(t, http.StatusOK, w.Code)
+		service.AssertExpectations(t)
+	})
+
+	t.Run("delete not found", func(t *testing.T) {
+		service := new(MockRuleService)
+		service.On("DeleteRule", mock.Anything, uint(7), uint(5)).
+			Return(pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "规则不存在"))
+
+		w := httptest.NewRecorder()
+		setupFolderRuleRouter(service).ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/folder-rules/5", nil))
+
+		assert.Equal(t, utils.CodeNotFound, decodeShareResponse(t, w).Code)
+	})
+
+	t.Run("invalid id", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		setupFolderRuleRouter(new(MockRuleService)).ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/folder-rules/abc", nil))
+
+		assert.Equal(t, utils.CodeBadRequest, decodeShareResponse(t, w).Code)
+	})
+}
+
+func TestFolderRuleHandler_ListRuleLogs(t *testing.T) {
+	service := new(MockRuleService)
+	service.On("ListLogs", mock.Anything, uint(7), uint(5), 2, maxRuleLogPageSize).
+		Return([]*models.FolderRuleLog{{ID: 9, RuleID: 5, Status: models.RuleLogStatusFailed}}, int64(101), nil)
+
+	w := httptest.NewRecorder()
+	setupFolderRuleRouter(service).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/folder-rules/5/logs?page=2&page_size=500", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Data       []models.FolderRuleLog `json:"data"`
+		Pagination utils.Pagination       `json:"pagination"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Data, 1)
+	assert.Equal(t, models.RuleLogStatusFailed, resp.Data[0].Status)
+	assert.Equal(t, int64(101), resp.Pagination.TotalCount)
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/service/jobs"
+)
+
+// JobQueueHandler 后台任务队列处理器
+type JobQueueHandler struct {
+	queue  *jobs.Queue
+	logger *zap.Logger
+}
+
+// NewJobQueueHandler 创建后台任务队列处理器
+func NewJobQueueHandler(queue *jobs.Queue, logger *zap.Logger) *JobQueueHandler {
+	return &JobQueueHandler{
+		queue:  queue,
+		logger: logger,
+	}
+}
+
+// ListPools 查询后台任务工作池状态
+//
+// @Summary 查询后台任务工作池状态
+// @Description 返回本实例各任务类型(缩略图、转码等)工作池的并发上限、正在执行和排队的任务数、最早排队任务的等待时间、平均排队时间以及累计成功、失败和因队列满被拒绝的任务数
+// @Tags 系统
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response{data=[]jobs.PoolMetrics} "工作池状态"
+// @Failure 403 {object} utils.Response "需要管理员权限"
+// @Router /api/v1/admin/jobs/pools [get]
+func (h *JobQueueHandler) ListPools(c *gin.Context) {
+	utils.Success(c, h.queue.Metrics())
+}
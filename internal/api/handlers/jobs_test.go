@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/service/jobs"
+)
+
+func TestJobQueueHandler_ListPools(t *testing.T) {
+	queue := jobs.NewQueue(jobs.Options{Pools: map[string]jobs.PoolOptions{
+		jobs.TypeTranscode: {Concurrency: 2, QueueSize: 10},
+		jobs.TypeThumbnail: {Concurrency: 4},
+	}}, nil)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/admin/jobs/pools", NewJobQueueHandler(queue, zap.NewNop()).ListPools)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/jobs/pools", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	resp := decodeShareResponse(t, w)
+	assert.Equal(t, utils.CodeSuccess, resp.Code)
+	pools := resp.Data.([]interface{})
+	require.Len(t, pools, 2)
+	first := pools[0].(map[string]interface{})
+	assert.Equal(t, jobs.TypeThumbnail, first["type"])
+	assert.EqualValues(t, 4, first["concurrency"])
+	assert.EqualValues(t, 3, first["batch_concurrency"])
+	assert.EqualValues(t, 0, first["running"])
+}
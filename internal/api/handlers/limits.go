@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/service/limits"
+)
+
+// LimitsHandler 有效限制查询处理器
+type LimitsHandler struct {
+	limitsService limits.LimitsService
+	logger        *zap.Logger
+}
+
+// NewLimitsHandler 创建有效限制查询处理器
+func NewLimitsHandler(limitsService limits.LimitsService, logger *zap.Logger) *LimitsHandler {
+	return &LimitsHandler{
+		limitsService: limitsService,
+		logger:        logger,
+	}
+}
+
+// GetLimits 获取当前用户的有效限制
+//
+// @Summary 获取当前用户的有效限制
+// @Description 返回最大文件大小、分片大小、并行度、允许的文件类型、限流和分享上限等，客户端据此自动配置
+// @Tags 系统
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response{data=limits.EffectiveLimits} "获取成功"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 404 {object} utils.Response "用户不存在"
+// @Failure 500 {object} utils.Response "内部服务器错误"
+// @Router /api/v1/limits [get]
+func (h *LimitsHandler) GetLimits(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	effective, err := h.limitsService.GetEffectiveLimits(c.Request.Context(), userID)
+	if err != nil {
+		h.logger.Error("Failed to resolve effective limits",
+			zap.Uint("user_id", userID),
+			zap.Error(err),
+			zap.String("ip", c.ClientIP()))
+		respondServiceError(c, err, "获取限制信息失败")
+		return
+	}
+
+	utils.Success(c, effective)
+}
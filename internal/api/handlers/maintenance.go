@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/service/maintenance"
+)
+
+// MaintenanceHandler 定期维护任务处理器
+type MaintenanceHandler struct {
+	scheduler *maintenance.Scheduler
+	logger    *zap.Logger
+}
+
+// NewMaintenanceHandler 创建定期维护任务处理器
+func NewMaintenanceHandler(scheduler *maintenance.Scheduler, logger *zap.Logger) *MaintenanceHandler {
+	return &MaintenanceHandler{
+		scheduler: scheduler,
+		logger:    logger,
+	}
+}
+
+// ListTasks 查询维护任务执行情况
+//
+// @Summary 查询维护任务执行情况
+// @Description 返回本实例各定期维护任务(过期验证码、会话、分享及进程内计数清理)的执行间隔、累计执行次数、清理记录数、最近一次执行结果和下一次计划执行时间
+// @Tags 系统
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response{data=[]maintenance.TaskMetrics} "任务执行情况"
+// @Failure 403 {object} utils.Response "需要管理员权限"
+// @Router /api/v1/admin/maintenance/tasks [get]
+func (h *MaintenanceHandler) ListTasks(c *gin.Context) {
+	utils.Success(c, h.scheduler.Metrics())
+}
+
+// SystemJobsResponse 定期任务健康状况
+type SystemJobsResponse struct {
+	Healthy bool                      `json:"healthy"` // 全部任务健康
+	Jobs    []maintenance.TaskMetrics `json:"jobs"`    // 各任务状态
+}
+
+// ListJobs 查询定期任务健康状况
+//
+// @Summary 查询定期任务健康状况
+// @Description 返回本实例调度的全部定期任务(过期验证码、上传分片、分享、会话等清理)的状态、是否健康、最近一次执行时间和结果，以及因其他实例持有任务锁而跳过的次数
+// @Tags 系统
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response{data=SystemJobsResponse} "任务健康状况"
+// @Failure 403 {object} utils.Response "需要管理员权限"
+// @Router /api/v1/system/jobs [get]
+func (h *MaintenanceHandler) ListJobs(c *gin.Context) {
+	response := SystemJobsResponse{Healthy: true, Jobs: h.scheduler.Metrics()}
+	for _, job := range response.Jobs {
+		if !job.Healthy {
+			response.Healthy = false
+			break
+		}
+	}
+	utils.Success(c, response)
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/service/maintenance"
+)
+
+func TestMaintenanceHandler_ListTasks(t *testing.T) {
+	scheduler := maintenance.NewScheduler(maintenance.Options{Interval: time.Hour}, nil)
+	for _, name := range []string{maintenance.TaskSessions, maintenance.TaskExpiredShares} {
+		require.NoError(t, scheduler.Register(maintenance.Task{Name: name, Run: func(context.Context) (int64, error) {
+			return 0, nil
+		}}))
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/admin/maintenance/tasks", NewMaintenanceHandler(scheduler, zap.NewNop()).ListTasks)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/maintenance/tasks", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	resp := decodeShareResponse(t, w)
+	assert.Equal(t, utils.CodeSuccess, resp.Code)
+	tasks := resp.Data.([]interface{})
+	require.Len(t, tasks, 2)
+	first := tasks[0].(map[string]interface{})
+	assert.Equal(t, maintenance.TaskExpiredShares, first["name"])
+	assert.Equal(t, "1h0m0s", first["interval"])
+	assert.EqualValues(t, 0, first["runs"])
+}
+
+func TestMaintenanceHandler_ListJobs(t *testing.T) {
+	scheduler := maintenance.NewScheduler(maintenance.Options{Interval: time.Hour}, nil)
+	require.NoError(t, scheduler.Register(maintenance.Task{Name: maintenance.TaskUploadChunks, Interval: 5 * time.Millisecond, Run: func(context.Context) (int64, error) {
+		return 0, errors.New("storage unavailable")
+	}}))
+	require.NoError(t, scheduler.Register(maintenance.PruneTask(maintenance.TaskRateLimits)))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/system/jobs", NewMaintenanceHandler(scheduler, zap.NewNop()).ListJobs)
+	serve := func() map[string]interface{} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/system/jobs", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		return decodeShareResponse(t, w).Data.(map[string]interface{})
+	}
+
+	data := serve()
+	assert.Equal(t, true, data["healthy"], "尚未执行的任务视为健康")
+	jobs := data["jobs"].([]interface{})
+	require.Len(t, jobs, 2)
+	assert.Equal(t, maintenance.TaskRateLimits, jobs[0].(map[string]interface{})["name"])
+	assert.Equal(t, true, jobs[0].(map[string]interface{})["local"])
+	assert.Equal(t, maintenance.StatusPending, jobs[1].(map[string]interface{})["status"])
+
+	ctx, cancel := context.WithCancel(context.Background())
+	scheduler.Start(ctx)
+	assert.Eventually(t, func() bool { return serve()["healthy"] == false }, time.Second, 5*time.Millisecond)
+	cancel()
+	scheduler.Wait()
+
+	chunks := serve()["jobs"].([]interface{})[1].(map[string]interface{})
+	assert.Equal(t, maintenance.StatusFailed, chunks["status"])
+	assert.Equal(t, "storage unavailable", chunks["last_error"])
+}
@@ -96,6 +96,11 @@ func (m *MockUserService) ActivateUser(ctx context.Context, userID uint) error {
 	return args.Error(0)
 }
 
+func (m *MockUserService) CancelDeletion(ctx context.Context, userID uint) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
 func (m *MockUserService) DeactivateUser(ctx context.Context, userID uint) error {
 	args := m.Called(ctx, userID)
 	return args.Error(0)
@@ -144,6 +149,11 @@ func (m *MockUserService) UpdateStorageUsed(ctx context.Context, userID uint, si
 	return args.Error(0)
 }
 
+func (m *MockUserService) UpdateStorageQuota(ctx context.Context, userID uint, quota int64) error {
+	args := m.Called(ctx, userID, quota)
+	return args.Error(0)
+}
+
 func (m *MockUserService) CheckStorageQuota(ctx context.Context, userID uint, requiredSize int64) (bool, error) {
 	args := m.Called(ctx, userID, requiredSize)
 	return args.Bool(0), args.Error(1)
@@ -175,3 +185,15 @@ func (m *MockUserService) DeleteUserPreference(ctx context.Context, userID uint,
 	args := m.Called(ctx, userID, category, key)
 	return args.Error(0)
 }
+
+// recordingTxManager 记录事务调用的事务管理器，fn返回的错误即视为回滚
+type recordingTxManager struct {
+	calls int
+	err   error
+}
+
+func (m *recordingTxManager) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	m.calls++
+	m.err = fn(ctx)
+	return m.err
+}
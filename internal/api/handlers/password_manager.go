@@ -1,13 +1,19 @@
 package handlers
 
 import (
+	"context"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 
+	"cloudpan/internal/pkg/cache"
+	"cloudpan/internal/pkg/database"
 	"cloudpan/internal/pkg/errors"
 	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+	"cloudpan/internal/service/audit"
 	"cloudpan/internal/service/user"
 	"cloudpan/internal/service/verification"
 )
@@ -68,11 +74,14 @@ type PasswordStrengthResponse struct {
 
 // PasswordManagerHandler 密码管理处理器
 type PasswordManagerHandler struct {
+	securityAudit
 	userService         user.UserService
 	verificationService verification.VerificationService
 	logger              *zap.Logger
 	validator           utils.ParameterValidator
 	passwordHasher      utils.PasswordHasher
+	refreshStore        cache.RefreshTokenStore
+	txManager           database.TxManager
 }
 
 // NewPasswordManagerHandler 创建新的密码管理处理器
@@ -90,6 +99,19 @@ func NewPasswordManagerHandler(
 	}
 }
 
+// SetRefreshTokenStore 设置刷新令牌登记存储，未设置时使用全局刷新令牌登记存储
+func (h *PasswordManagerHandler) SetRefreshTokenStore(store cache.RefreshTokenStore) {
+	h.refreshStore = store
+}
+
+// SetTxManager 设置事务管理器
+//
+// 设置后重置密码时更新密码和标记验证码已使用在同一事务中完成，验证码已被并发使用时密码不变；
+// 未设置时标记验证码失败不影响密码重置
+func (h *PasswordManagerHandler) SetTxManager(txManager database.TxManager) {
+	h.txManager = txManager
+}
+
 // ForgotPassword 忘记密码
 //
 // @Summary 忘记密码
@@ -272,8 +294,27 @@ func (h *PasswordManagerHandler) ResetPassword(c *gin.Context) {
 		return
 	}
 
-	// 更新用户密码
-	if err := h.userService.UpdatePassword(ctx, user.ID, hashedPassword); err != nil {
+	// 更新用户密码并标记验证码为已使用，设置事务管理器时两者同时生效
+	var codeErr error
+	err = withTransaction(ctx, h.txManager, func(ctx context.Context) error {
+		if err := h.userService.UpdatePassword(ctx, user.ID, hashedPassword); err != nil {
+			return err
+		}
+		codeErr = h.verificationService.CompletePasswordReset(ctx, verificationCode.ID)
+		if h.txManager != nil {
+			return codeErr
+		}
+		return nil
+	})
+	if codeErr != nil && h.txManager != nil {
+		h.logger.Warn("Verification code already used during password reset",
+			zap.Uint("code_id", verificationCode.ID),
+			zap.Error(codeErr),
+			zap.String("ip", c.ClientIP()))
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "验证码无效")
+		return
+	}
+	if err != nil {
 		h.logger.Error("Failed to update user password",
 			zap.String("email", req.Email),
 			zap.Uint("user_id", user.ID),
@@ -282,15 +323,17 @@ func (h *PasswordManagerHandler) ResetPassword(c *gin.Context) {
 		utils.InternalErrorWithMessage(c, "密码更新失败")
 		return
 	}
-
-	// 标记验证码为已使用
-	if err := h.verificationService.CompletePasswordReset(ctx, verificationCode.ID); err != nil {
+	if codeErr != nil {
 		h.logger.Error("Failed to mark verification code as used",
 			zap.Uint("code_id", verificationCode.ID),
-			zap.Error(err))
+			zap.Error(codeErr))
 		// 不影响密码重置成功
 	}
 
+	// 密码已重置，吊销已签发的刷新令牌，其他设备需要使用新密码重新登录
+	h.revokeRefreshTokens(ctx, user.ID)
+	h.recordSecurity(c, h.logger, passwordEvent(user.ID, audit.SecurityActionPasswordReset, "", map[string]interface{}{"method": "email_code"}))
+
 	h.logger.Info("Password reset completed successfully",
 		zap.String("email", req.Email),
 		zap.Uint("user_id", user.ID),
@@ -371,6 +414,8 @@ func (h *PasswordManagerHandler) ChangePassword(c *gin.Context) {
 		h.logger.Warn("Current password verification failed",
 			zap.Uint("user_id", currentUserID),
 			zap.String("ip", c.ClientIP()))
+		h.recordSecurity(c, h.logger, passwordEvent(currentUserID, audit.SecurityActionPasswordChange, models.AuditResultFailure,
+			map[string]interface{}{"reason": "invalid_current_password"}))
 		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "当前密码错误")
 		return
 	}
@@ -396,6 +441,10 @@ func (h *PasswordManagerHandler) ChangePassword(c *gin.Context) {
 		return
 	}
 
+	// 密码已修改，吊销已签发的刷新令牌
+	h.revokeRefreshTokens(ctx, currentUserID)
+	h.recordSecurity(c, h.logger, passwordEvent(currentUserID, audit.SecurityActionPasswordChange, "", nil))
+
 	h.logger.Info("Password changed successfully",
 		zap.Uint("user_id", currentUserID),
 		zap.String("ip", c.ClientIP()))
@@ -408,6 +457,34 @@ func (h *PasswordManagerHandler) ChangePassword(c *gin.Context) {
 	utils.SuccessWithMessage(c, "密码修改成功", response)
 }
 
+// passwordEvent 构造密码修改或重置的安全审计记录，不记录密码或其哈希
+func passwordEvent(userID uint, action, result string, details map[string]interface{}) *audit.Event {
+	return &audit.Event{
+		UserID:       userID,
+		Action:       action,
+		ResourceType: audit.ResourceUser,
+		ResourceID:   strconv.FormatUint(uint64(userID), 10),
+		Result:       result,
+		Details:      details,
+	}
+}
+
+// revokeRefreshTokens 吊销用户的全部刷新令牌，失败时仅记录日志，不影响密码修改结果
+func (h *PasswordManagerHandler) revokeRefreshTokens(ctx context.Context, userID uint) {
+	store := h.refreshStore
+	if store == nil {
+		store = cache.DefaultRefreshTokenStore()
+	}
+	if store == nil {
+		return
+	}
+	if err := store.RevokeAllForUser(ctx, uint64(userID)); err != nil {
+		h.logger.Error("Failed to revoke refresh tokens after password update",
+			zap.Uint("user_id", userID),
+			zap.Error(err))
+	}
+}
+
 // CheckPasswordStrength 检查密码强度
 //
 // @Summary 检查密码强度
@@ -96,9 +96,9 @@ func (m *MockVerificationService) InvalidateCode(ctx context.Context, codeID uin
 	return args.Error(0)
 }
 
-func (m *MockVerificationService) CleanupExpiredCodes(ctx context.Context) error {
+func (m *MockVerificationService) CleanupExpiredCodes(ctx context.Context) (int64, error) {
 	args := m.Called(ctx)
-	return args.Error(0)
+	return args.Get(0).(int64), args.Error(1)
 }
 
 func (m *MockVerificationService) GetAttemptCount(ctx context.Context, target, codeType string, timeWindow time.Duration) (int, error) {
@@ -145,6 +145,21 @@ func (m *MockVerificationService) GetUserActiveCodes(ctx context.Context, userID
 	return args.Get(0).([]*models.VerificationCode), args.Error(1)
 }
 
+func (m *MockVerificationService) GenerateTOTPSecret() (string, error) {
+	args := m.Called()
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockVerificationService) TOTPProvisioningURI(issuer, account, secret string) string {
+	args := m.Called(issuer, account, secret)
+	return args.String(0)
+}
+
+func (m *MockVerificationService) ValidateTOTPCode(secret, code string, lastUsedStep int64) (int64, error) {
+	args := m.Called(secret, code, lastUsedStep)
+	return args.Get(0).(int64), args.Error(1)
+}
+
 // 测试数据
 func createTestUser() *models.User {
 	user := &models.User{
@@ -432,6 +447,39 @@ func TestPasswordManagerHandler_ResetPassword(t *testing.T) {
 		mockVerificationService.AssertExpectations(t)
 	})
 
+	t.Run("事务中验证码已被使用时回滚", func(t *testing.T) {
+		mockUserService := new(MockUserService)
+		mockVerificationService := new(MockVerificationService)
+		txManager := &recordingTxManager{}
+
+		handler := NewPasswordManagerHandler(mockUserService, mockVerificationService, zap.NewNop())
+		handler.SetTxManager(txManager)
+
+		mockVerificationService.On("VerifyPasswordResetCode", mock.Anything, "test@example.com", "123456").Return(createTestVerificationCode(), nil)
+		mockUserService.On("GetUserByEmail", mock.Anything, "test@example.com").Return(createTestUser(), nil)
+		mockUserService.On("UpdatePassword", mock.Anything, uint(1), mock.AnythingOfType("string")).Return(nil)
+		mockVerificationService.On("CompletePasswordReset", mock.Anything, uint(1)).Return(errors.NewValidationError("code", "验证码不存在或已使用"))
+
+		body, _ := json.Marshal(ResetPasswordRequest{
+			Email:            "test@example.com",
+			VerificationCode: "123456",
+			NewPassword:      "ComplexP@ssw0rd2024!",
+			ConfirmPassword:  "ComplexP@ssw0rd2024!",
+		})
+		req, _ := http.NewRequest("POST", "/password/reset", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = req
+
+		handler.ResetPassword(c)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, 1, txManager.calls)
+		assert.Error(t, txManager.err, "验证码标记失败时事务回滚，密码不变")
+		mockUserService.AssertExpectations(t)
+	})
+
 	t.Run("无效验证码", func(t *testing.T) {
 		// 为每个测试用例创建新的mock对象
 		mockUserService := new(MockUserService)
@@ -0,0 +1,475 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/service/sso"
+)
+
+// SCIM响应参数
+const (
+	scimContentType      = "application/scim+json"
+	scimClientContextKey = "scim_client" // 认证通过的身份提供方
+)
+
+// SCIMError SCIM错误响应
+type SCIMError struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status" example:"409"`
+	SCIMType string   `json:"scimType,omitempty" example:"uniqueness"`
+	Detail   string   `json:"detail,omitempty"`
+}
+
+// SCIMHandler 企业目录同步(SCIM 2.0)处理器
+//
+// 接口按SCIM协议返回 application/scim+json，错误使用SCIM错误格式而不是统一响应格式
+type SCIMHandler struct {
+	service sso.SCIMService
+	logger  *zap.Logger
+}
+
+// NewSCIMHandler 创建企业目录同步处理器
+func NewSCIMHandler(service sso.SCIMService, logger *zap.Logger) *SCIMHandler {
+	return &SCIMHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// RequireToken 校验 Authorization: Bearer 中的目录同步令牌
+func (h *SCIMHandler) RequireToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			respondSCIMError(c, http.StatusUnauthorized, "", "缺少目录同步令牌")
+			c.Abort()
+			return
+		}
+		client, err := h.service.Authenticate(c.Request.Context(), strings.TrimSpace(token))
+		if err != nil {
+			if errors.Is(err, sso.ErrSCIMUnauthorized) {
+				h.logger.Warn("SCIM request rejected", zap.String("ip", c.ClientIP()), zap.Error(err))
+				respondSCIMError(c, http.StatusUnauthorized, "", "目录同步令牌无效")
+			} else {
+				h.logger.Error("Failed to authenticate SCIM token", zap.Error(err))
+				respondSCIMError(c, http.StatusInternalServerError, "", "目录同步令牌校验失败")
+			}
+			c.Abort()
+			return
+		}
+		c.Set(scimClientContextKey, client)
+		c.Next()
+	}
+}
+
+// ServiceProviderConfig 查询服务支持的SCIM功能
+//
+// @Summary SCIM服务配置
+// @Description 目录配置SCIM连接时读取，支持PATCH，不支持批量操作、排序、ETag和修改密码，过滤只支持 attr eq "value"
+// @Tags 目录同步
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "服务配置"
+// @Failure 401 {object} SCIMError "令牌无效"
+// @Router /api/v1/scim/v2/ServiceProviderConfig [get]
+func (h *SCIMHandler) ServiceProviderConfig(c *gin.Context) {
+	respondSCIM(c, http.StatusOK, gin.H{
+		"schemas":        []string{sso.SCIMSchemaServiceProviderConfig},
+		"patch":          gin.H{"supported": true},
+		"bulk":           gin.H{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         gin.H{"supported": true, "maxResults": sso.DefaultSCIMPageSize},
+		"changePassword": gin.H{"supported": false},
+		"sort":           gin.H{"supported": false},
+		"etag":           gin.H{"supported": false},
+		"authenticationSchemes": []gin.H{{
+			"type":        "oauthbearertoken",
+			"name":        "Bearer Token",
+			"description": "管理员为身份提供方签发的目录同步令牌",
+			"primary":     true,
+		}},
+	})
+}
+
+// ListUsers 查询同步的用户
+//
+// @Summary 查询SCIM用户
+// @Description 返回该身份提供方同步的用户，支持按userName、externalId、emails.value过滤
+// @Tags 目录同步
+// @Produce json
+// @Security BearerAuth
+// @Param filter query string false "过滤表达式" example(userName eq "alice@acme.com")
+// @Param startIndex query int false "起始序号(从1开始)"
+// @Param count query int false "每页数量(最大100)"
+// @Success 200 {object} sso.SCIMListResponse{Resources=[]sso.SCIMUser} "查询成功"
+// @Failure 400 {object} SCIMError "过滤表达式不支持"
+// @Failure 401 {object} SCIMError "令牌无效"
+// @Router /api/v1/scim/v2/Users [get]
+func (h *SCIMHandler) ListUsers(c *gin.Context) {
+	query, ok := parseSCIMListQuery(c)
+	if !ok {
+		return
+	}
+	list, err := h.service.ListUsers(c.Request.Context(), scimClient(c), query)
+	if err != nil {
+		h.respondError(c, err, "查询用户失败")
+		return
+	}
+	respondSCIM(c, http.StatusOK, list)
+}
+
+// GetUser 获取同步的用户
+//
+// @Summary 获取SCIM用户
+// @Tags 目录同步
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "用户ID(UUID)"
+// @Success 200 {object} sso.SCIMUser "获取成功"
+// @Failure 401 {object} SCIMError "令牌无效"
+// @Failure 404 {object} SCIMError "用户不存在"
+// @Router /api/v1/scim/v2/Users/{id} [get]
+func (h *SCIMHandler) GetUser(c *gin.Context) {
+	user, err := h.service.GetUser(c.Request.Context(), scimClient(c), c.Param("id"))
+	if err != nil {
+		h.respondError(c, err, "获取用户失败")
+		return
+	}
+	respondSCIM(c, http.StatusOK, user)
+}
+
+// CreateUser 开通或关联用户
+//
+// @Summary 创建SCIM用户
+// @Description 邮箱必须属于该身份提供方已验证的域名。邮箱已注册时关联已有账户，否则开通新账户；active=false时账户创建后即停用
+// @Tags 目录同步
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body sso.SCIMUser true "用户"
+// @Success 201 {object} sso.SCIMUser "创建成功"
+// @Failure 400 {object} SCIMError "参数错误或邮箱域名未认证"
+// @Failure 401 {object} SCIMError "令牌无效"
+// @Failure 409 {object} SCIMError "userName、externalId已存在或账户已同步"
+// @Router /api/v1/scim/v2/Users [post]
+func (h *SCIMHandler) CreateUser(c *gin.Context) {
+	var req sso.SCIMUser
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondSCIMError(c, http.StatusBadRequest, "invalidSyntax", "请求格式错误")
+		return
+	}
+	user, err := h.service.CreateUser(c.Request.Context(), scimClient(c), &req)
+	if err != nil {
+		h.respondError(c, err, "创建用户失败")
+		return
+	}
+	respondSCIM(c, http.StatusCreated, user)
+}
+
+// ReplaceUser 替换用户属性
+//
+// @Summary 替换SCIM用户
+// @Description 修改邮箱时新邮箱不能被其他账户使用；省略active时不改变账户状态
+// @Tags 目录同步
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "用户ID(UUID)"
+// @Param request body sso.SCIMUser true "用户"
+// @Success 200 {object} sso.SCIMUser "替换成功"
+// @Failure 400 {object} SCIMError "参数错误"
+// @Failure 401 {object} SCIMError "令牌无效"
+// @Failure 404 {object} SCIMError "用户不存在"
+// @Failure 409 {object} SCIMError "userName或邮箱已被使用"
+// @Router /api/v1/scim/v2/Users/{id} [put]
+func (h *SCIMHandler) ReplaceUser(c *gin.Context) {
+	var req sso.SCIMUser
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondSCIMError(c, http.StatusBadRequest, "invalidSyntax", "请求格式错误")
+		return
+	}
+	user, err := h.service.ReplaceUser(c.Request.Context(), scimClient(c), c.Param("id"), &req)
+	if err != nil {
+		h.respondError(c, err, "替换用户失败")
+		return
+	}
+	respondSCIM(c, http.StatusOK, user)
+}
+
+// PatchUser 修改用户属性
+//
+// @Summary 修改SCIM用户
+// @Description 支持修改active、userName、externalId、displayName、name和emails，其他属性被忽略。active=false时停用账户并吊销登录令牌，active=true时恢复被停用的账户
+// @Tags 目录同步
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "用户ID(UUID)"
+// @Param request body sso.SCIMPatchRequest true "PATCH操作"
+// @Success 200 {object} sso.SCIMUser "修改成功"
+// @Failure 400 {object} SCIMError "操作无效"
+// @Failure 401 {object} SCIMError "令牌无效"
+// @Failure 404 {object} SCIMError "用户不存在"
+// @Failure 409 {object} SCIMError "userName或邮箱已被使用"
+// @Router /api/v1/scim/v2/Users/{id} [patch]
+func (h *SCIMHandler) PatchUser(c *gin.Context) {
+	var req sso.SCIMPatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondSCIMError(c, http.StatusBadRequest, "invalidSyntax", "请求格式错误")
+		return
+	}
+	user, err := h.service.PatchUser(c.Request.Context(), scimClient(c), c.Param("id"), &req)
+	if err != nil {
+		h.respondError(c, err, "修改用户失败")
+		return
+	}
+	respondSCIM(c, http.StatusOK, user)
+}
+
+// DeleteUser 取消同步用户
+//
+// @Summary 删除SCIM用户
+// @Description 停用账户、吊销登录令牌并移出同步的团队，账户和文件保留
+// @Tags 目录同步
+// @Security BearerAuth
+// @Param id path string true "用户ID(UUID)"
+// @Success 204 "删除成功"
+// @Failure 401 {object} SCIMError "令牌无效"
+// @Failure 404 {object} SCIMError "用户不存在"
+// @Router /api/v1/scim/v2/Users/{id} [delete]
+func (h *SCIMHandler) DeleteUser(c *gin.Context) {
+	if err := h.service.DeleteUser(c.Request.Context(), scimClient(c), c.Param("id")); err != nil {
+		h.respondError(c, err, "删除用户失败")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListGroups 查询同步的组
+//
+// @Summary 查询SCIM组
+// @Description 返回该身份提供方同步的组，支持按displayName、externalId过滤，excludedAttributes=members时不返回成员
+// @Tags 目录同步
+// @Produce json
+// @Security BearerAuth
+// @Param filter query string false "过滤表达式" example(displayName eq "Engineering")
+// @Param startIndex query int false "起始序号(从1开始)"
+// @Param count query int false "每页数量(最大100)"
+// @Param excludedAttributes query string false "不返回的属性"
+// @Success 200 {object} sso.SCIMListResponse{Resources=[]sso.SCIMGroup} "查询成功"
+// @Failure 400 {object} SCIMError "过滤表达式不支持"
+// @Failure 401 {object} SCIMError "令牌无效"
+// @Router /api/v1/scim/v2/Groups [get]
+func (h *SCIMHandler) ListGroups(c *gin.Context) {
+	query, ok := parseSCIMListQuery(c)
+	if !ok {
+		return
+	}
+	list, err := h.service.ListGroups(c.Request.Context(), scimClient(c), query)
+	if err != nil {
+		h.respondError(c, err, "查询组失败")
+		return
+	}
+	respondSCIM(c, http.StatusOK, list)
+}
+
+// GetGroup 获取同步的组
+//
+// @Summary 获取SCIM组
+// @Tags 目录同步
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "组ID(团队UUID)"
+// @Success 200 {object} sso.SCIMGroup "获取成功"
+// @Failure 401 {object} SCIMError "令牌无效"
+// @Failure 404 {object} SCIMError "组不存在"
+// @Router /api/v1/scim/v2/Groups/{id} [get]
+func (h *SCIMHandler) GetGroup(c *gin.Context) {
+	group, err := h.service.GetGroup(c.Request.Context(), scimClient(c), c.Param("id"))
+	if err != nil {
+		h.respondError(c, err, "获取组失败")
+		return
+	}
+	respondSCIM(c, http.StatusOK, group)
+}
+
+// CreateGroup 创建组
+//
+// @Summary 创建SCIM组
+// @Description 创建对应的团队并加入成员，成员必须是该身份提供方同步的用户
+// @Tags 目录同步
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body sso.SCIMGroup true "组"
+// @Success 201 {object} sso.SCIMGroup "创建成功"
+// @Failure 400 {object} SCIMError "参数错误、成员无效或超过成员上限"
+// @Failure 401 {object} SCIMError "令牌无效"
+// @Failure 409 {object} SCIMError "组名称已存在"
+// @Router /api/v1/scim/v2/Groups [post]
+func (h *SCIMHandler) CreateGroup(c *gin.Context) {
+	var req sso.SCIMGroup
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondSCIMError(c, http.StatusBadRequest, "invalidSyntax", "请求格式错误")
+		return
+	}
+	group, err := h.service.CreateGroup(c.Request.Context(), scimClient(c), &req)
+	if err != nil {
+		h.respondError(c, err, "创建组失败")
+		return
+	}
+	respondSCIM(c, http.StatusCreated, group)
+}
+
+// ReplaceGroup 替换组名称和成员
+//
+// @Summary 替换SCIM组
+// @Tags 目录同步
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "组ID(团队UUID)"
+// @Param request body sso.SCIMGroup true "组"
+// @Success 200 {object} sso.SCIMGroup "替换成功"
+// @Failure 400 {object} SCIMError "参数错误、成员无效或超过成员上限"
+// @Failure 401 {object} SCIMError "令牌无效"
+// @Failure 404 {object} SCIMError "组不存在"
+// @Failure 409 {object} SCIMError "组名称已存在"
+// @Router /api/v1/scim/v2/Groups/{id} [put]
+func (h *SCIMHandler) ReplaceGroup(c *gin.Context) {
+	var req sso.SCIMGroup
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondSCIMError(c, http.StatusBadRequest, "invalidSyntax", "请求格式错误")
+		return
+	}
+	group, err := h.service.ReplaceGroup(c.Request.Context(), scimClient(c), c.Param("id"), &req)
+	if err != nil {
+		h.respondError(c, err, "替换组失败")
+		return
+	}
+	respondSCIM(c, http.StatusOK, group)
+}
+
+// PatchGroup 修改组名称或增删成员
+//
+// @Summary 修改SCIM组
+// @Description 支持修改displayName、externalId，增加、删除或替换members，删除单个成员可使用 members[value eq "id"] 路径
+// @Tags 目录同步
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "组ID(团队UUID)"
+// @Param request body sso.SCIMPatchRequest true "PATCH操作"
+// @Success 200 {object} sso.SCIMGroup "修改成功"
+// @Failure 400 {object} SCIMError "操作无效、成员无效或超过成员上限"
+// @Failure 401 {object} SCIMError "令牌无效"
+// @Failure 404 {object} SCIMError "组不存在"
+// @Failure 409 {object} SCIMError "组名称已存在"
+// @Router /api/v1/scim/v2/Groups/{id} [patch]
+func (h *SCIMHandler) PatchGroup(c *gin.Context) {
+	var req sso.SCIMPatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondSCIMError(c, http.StatusBadRequest, "invalidSyntax", "请求格式错误")
+		return
+	}
+	group, err := h.service.PatchGroup(c.Request.Context(), scimClient(c), c.Param("id"), &req)
+	if err != nil {
+		h.respondError(c, err, "修改组失败")
+		return
+	}
+	respondSCIM(c, http.StatusOK, group)
+}
+
+// DeleteGroup 删除组
+//
+// @Summary 删除SCIM组
+// @Description 移出全部成员并删除对应的团队
+// @Tags 目录同步
+// @Security BearerAuth
+// @Param id path string true "组ID(团队UUID)"
+// @Success 204 "删除成功"
+// @Failure 401 {object} SCIMError "令牌无效"
+// @Failure 404 {object} SCIMError "组不存在"
+// @Router /api/v1/scim/v2/Groups/{id} [delete]
+func (h *SCIMHandler) DeleteGroup(c *gin.Context) {
+	if err := h.service.DeleteGroup(c.Request.Context(), scimClient(c), c.Param("id")); err != nil {
+		h.respondError(c, err, "删除组失败")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// respondError 将服务层错误转换为SCIM错误响应
+func (h *SCIMHandler) respondError(c *gin.Context, err error, fallbackMessage string) {
+	switch {
+	case errors.Is(err, sso.ErrSCIMInvalidFilter):
+		respondSCIMError(c, http.StatusBadRequest, "invalidFilter", err.Error())
+	case errors.Is(err, sso.ErrSCIMInvalidPath):
+		respondSCIMError(c, http.StatusBadRequest, "invalidPath", err.Error())
+	case pkgErrors.IsNotFoundError(err):
+		respondSCIMError(c, http.StatusNotFound, "", err.Error())
+	case errors.Is(err, pkgErrors.ErrResourceExists):
+		respondSCIMError(c, http.StatusConflict, "uniqueness", err.Error())
+	case pkgErrors.IsValidationError(err):
+		respondSCIMError(c, http.StatusBadRequest, "invalidValue", err.Error())
+	case pkgErrors.IsPermissionError(err):
+		respondSCIMError(c, http.StatusForbidden, "", err.Error())
+	default:
+		h.logger.Error("SCIM request failed", zap.String("path", c.FullPath()), zap.Error(err))
+		respondSCIMError(c, http.StatusInternalServerError, "", fallbackMessage)
+	}
+}
+
+// parseSCIMListQuery 解析列表查询参数，参数无效时写入错误响应
+func parseSCIMListQuery(c *gin.Context) (*sso.SCIMListQuery, bool) {
+	query := &sso.SCIMListQuery{Filter: c.Query("filter"), StartIndex: 1, Count: -1}
+	for _, param := range []struct {
+		name  string
+		value *int
+	}{{"startIndex", &query.StartIndex}, {"count", &query.Count}} {
+		raw := c.Query(param.name)
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < 0 {
+			respondSCIMError(c, http.StatusBadRequest, "invalidValue", param.name+"必须是非负整数")
+			return nil, false
+		}
+		*param.value = value
+	}
+	for _, attr := range strings.Split(c.Query("excludedAttributes"), ",") {
+		if strings.EqualFold(strings.TrimSpace(attr), "members") {
+			query.ExcludeMembers = true
+		}
+	}
+	return query, true
+}
+
+// scimClient 返回认证中间件写入的身份提供方
+func scimClient(c *gin.Context) *sso.SCIMClient {
+	client, _ := c.MustGet(scimClientContextKey).(*sso.SCIMClient)
+	return client
+}
+
+// respondSCIM 以 application/scim+json 写入响应
+func respondSCIM(c *gin.Context, status int, body interface{}) {
+	c.Header("Content-Type", scimContentType)
+	c.JSON(status, body)
+}
+
+// respondSCIMError 写入SCIM错误响应
+func respondSCIMError(c *gin.Context, status int, scimType, detail string) {
+	respondSCIM(c, status, &SCIMError{
+		Schemas:  []string{sso.SCIMSchemaError},
+		Status:   strconv.Itoa(status),
+		SCIMType: scimType,
+		Detail:   detail,
+	})
+}
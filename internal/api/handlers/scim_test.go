@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/service/sso"
+)
+
+// stubSCIMService 只接受固定令牌的目录同步服务
+type stubSCIMService struct {
+	sso.SCIMService
+	err     error
+	query   *sso.SCIMListQuery
+	deleted string
+}
+
+func (s *stubSCIMService) Authenticate(_ context.Context, token string) (*sso.SCIMClient, error) {
+	if token != "scim_good" {
+		return nil, pkgErrors.WrapError(sso.ErrSCIMUnauthorized, "令牌无效")
+	}
+	return &sso.SCIMClient{ProviderID: 3, Tenant: "acme"}, nil
+}
+
+func (s *stubSCIMService) IssueToken(_ context.Context, providerID, adminID uint) (*sso.SCIMTokenInfo, error) {
+	if providerID != 3 {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "身份提供方不存在")
+	}
+	return &sso.SCIMTokenInfo{Token: "scim_good", TokenPrefix: "scim_go", CreatedBy: adminID}, nil
+}
+
+func (s *stubSCIMService) ListUsers(_ context.Context, _ *sso.SCIMClient, query *sso.SCIMListQuery) (*sso.SCIMListResponse, error) {
+	s.query = query
+	return &sso.SCIMListResponse{Schemas: []string{sso.SCIMSchemaListResponse}, StartIndex: query.StartIndex, Resources: []*sso.SCIMUser{}}, s.err
+}
+
+func (s *stubSCIMService) CreateUser(_ context.Context, client *sso.SCIMClient, req *sso.SCIMUser) (*sso.SCIMUser, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &sso.SCIMUser{Schemas: []string{sso.SCIMSchemaUser}, ID: "u-1", UserName: req.UserName}, nil
+}
+
+func (s *stubSCIMService) DeleteUser(_ context.Context, _ *sso.SCIMClient, id string) error {
+	s.deleted = id
+	return s.err
+}
+
+func setupSCIMRouter(service *stubSCIMService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler := NewSCIMHandler(service, zap.NewNop())
+	scim := router.Group("/scim/v2", handler.RequireToken())
+	scim.GET("/Users", handler.ListUsers)
+	scim.POST("/Users", handler.CreateUser)
+	scim.DELETE("/Users/:id", handler.DeleteUser)
+	return router
+}
+
+func serveSCIM(router *gin.Engine, method, path, token, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/scim+json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestSCIMHandler_RequireToken(t *testing.T) {
+	router := setupSCIMRouter(&stubSCIMService{})
+	for _, token := range []string{"", "scim_bad"} {
+		w := serveSCIM(router, http.MethodGet, "/scim/v2/Users", token, "")
+		require.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, "application/scim+json", w.Header().Get("Content-Type"))
+		var resp SCIMError
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, []string{sso.SCIMSchemaError}, resp.Schemas)
+		assert.Equal(t, "401", resp.Status)
+	}
+}
+
+func TestSCIMHandler_Users(t *testing.T) {
+	service := &stubSCIMService{}
+	router := setupSCIMRouter(service)
+
+	w := serveSCIM(router, http.MethodGet, `/scim/v2/Users?filter=userName+eq+%22alice%40acme.com%22&startIndex=3&count=2&excludedAttributes=members`, "scim_good", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, &sso.SCIMListQuery{Filter: `userName eq "alice@acme.com"`, StartIndex: 3, Count: 2, ExcludeMembers: true}, service.query)
+	assert.Contains(t, w.Body.String(), `"Resources":[]`)
+
+	w = serveSCIM(router, http.MethodGet, "/scim/v2/Users?count=abc", "scim_good", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = serveSCIM(router, http.MethodPost, "/scim/v2/Users", "scim_good", `{"schemas":["`+sso.SCIMSchemaUser+`"],"userName":"alice@acme.com","active":true}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "application/scim+json", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), `"id":"u-1"`)
+
+	w = serveSCIM(router, http.MethodDelete, "/scim/v2/Users/u-1", "scim_good", "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "u-1", service.deleted)
+}
+
+func TestSCIMHandler_Errors(t *testing.T) {
+	cases := []struct {
+		err      error
+		status   int
+		scimType string
+	}{
+		{pkgErrors.WrapError(pkgErrors.ErrResourceExists, "userName已存在"), http.StatusConflict, "uniqueness"},
+		{pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "邮箱域名未由该身份提供方认证"), http.StatusBadRequest, "invalidValue"},
+		{pkgErrors.WrapError(sso.ErrSCIMInvalidPath, "不支持的路径"), http.StatusBadRequest, "invalidPath"},
+		{pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "用户不存在"), http.StatusNotFound, ""},
+	}
+	for _, tc := range cases {
+		router := setupSCIMRouter(&stubSCIMService{err: tc.err})
+		w := serveSCIM(router, http.MethodPost, "/scim/v2/Users", "scim_good", `{"userName":"alice@acme.com"}`)
+		require.Equal(t, tc.status, w.Code, tc.err.Error())
+		var resp SCIMError
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, tc.scimType, resp.SCIMType)
+		assert.Equal(t, tc.err.Error(), resp.Detail)
+	}
+}
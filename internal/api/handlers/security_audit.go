@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/service/audit"
+)
+
+// securityAudit 处理器共用的安全审计记录，未设置审计服务时不记录
+type securityAudit struct {
+	securityAuditor audit.SecurityAuditService
+}
+
+// SetSecurityAuditService 设置安全审计服务，未设置时不记录安全审计日志
+func (a *securityAudit) SetSecurityAuditService(service audit.SecurityAuditService) {
+	a.securityAuditor = service
+}
+
+// recordSecurity 写入安全审计记录
+//
+// IP、User-Agent、请求ID、请求方法和URI从请求上下文填充，未指定操作用户时使用当前登录用户。
+// 写入失败不影响已完成的操作，在错误日志中保留完整记录以便补录
+func (a *securityAudit) recordSecurity(c *gin.Context, logger *zap.Logger, events ...*audit.Event) {
+	if a.securityAuditor == nil || len(events) == 0 {
+		return
+	}
+
+	currentUserID, _ := getCurrentUserID(c)
+	for _, event := range events {
+		if event.UserID == 0 {
+			event.UserID = currentUserID
+		}
+		event.IPAddress = c.ClientIP()
+		event.UserAgent = c.Request.UserAgent()
+		event.RequestID = c.GetString("request_id") // 请求ID中间件写入
+		event.RequestMethod = c.Request.Method
+		event.RequestURI = c.Request.URL.Path
+	}
+
+	if err := a.securityAuditor.Record(c.Request.Context(), events...); err != nil {
+		for _, event := range events {
+			logger.Error("Failed to record security audit log",
+				zap.Uint("user_id", event.UserID),
+				zap.String("action", event.Action),
+				zap.String("resource_type", event.ResourceType),
+				zap.String("resource_id", event.ResourceID),
+				zap.String("result", event.Result),
+				zap.Any("before", event.Before),
+				zap.Any("after", event.After),
+				zap.Any("details", event.Details),
+				zap.String("request_id", event.RequestID),
+				zap.Error(err))
+		}
+	}
+}
+
+// AdminSecurityAuditHandler 安全审计日志查询处理器
+type AdminSecurityAuditHandler struct {
+	service audit.SecurityAuditService
+	logger  *zap.Logger
+}
+
+// NewAdminSecurityAuditHandler 创建安全审计日志查询处理器
+func NewAdminSecurityAuditHandler(service audit.SecurityAuditService, logger *zap.Logger) *AdminSecurityAuditHandler {
+	return &AdminSecurityAuditHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// ListLogs 查询安全审计日志
+//
+// @Summary 查询安全审计日志
+// @Description 按用户、操作类型、操作分类、结果和时间范围分页查询安全相关操作记录，按时间倒序。
+// @Description 记录包含操作用户、IP、User-Agent、请求ID和操作前后的快照，写入后不可修改或删除。
+// @Description 操作分类：auth(登录、修改和重置密码)、share(创建分享)、permission(文件授权、团队角色)、delete(移入回收站、彻底删除、删除团队、移除成员)
+// @Tags 系统
+// @Produce json
+// @Security BearerAuth
+// @Param user_id query int false "操作用户ID"
+// @Param action query string false "操作类型，如 auth.login、auth.login_failed、auth.password_change、share.create、permission.grant、delete.file_permanent"
+// @Param category query string false "操作分类" Enums(auth, share, permission, delete, account)
+// @Param result query string false "操作结果" Enums(success, failure)
+// @Param from query string false "起始时间(RFC3339，包含)"
+// @Param to query string false "结束时间(RFC3339，不包含)"
+// @Param page query int false "页码，默认1"
+// @Param page_size query int false "每页记录数，默认20，最大100"
+// @Success 200 {object} utils.Response{data=[]models.AuditLog} "查询成功"
+// @Failure 400 {object} utils.Response "查询参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "需要管理员权限"
+// @Failure 500 {object} utils.Response "内部服务器错误"
+// @Router /api/v1/admin/security-logs [get]
+func (h *AdminSecurityAuditHandler) ListLogs(c *gin.Context) {
+	query := audit.SecurityQuery{
+		Action:   c.Query("action"),
+		Category: c.Query("category"),
+		Result:   c.Query("result"),
+	}
+	if value := c.Query("user_id"); value != "" {
+		userID, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			utils.ErrorWithMessage(c, utils.CodeBadRequest, "user_id格式错误")
+			return
+		}
+		query.UserID = uint(userID)
+	}
+	var ok bool
+	if query.From, ok = parseAuditTime(c, "from"); !ok {
+		return
+	}
+	if query.To, ok = parseAuditTime(c, "to"); !ok {
+		return
+	}
+	query.Page, _ = strconv.Atoi(c.Query("page"))
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	query.PageSize, _ = strconv.Atoi(c.Query("page_size"))
+	if query.PageSize < 1 {
+		query.PageSize = defaultAuditPageSize
+	}
+	query.PageSize = min(query.PageSize, maxAuditPageSize)
+
+	logs, total, err := h.service.List(c.Request.Context(), query)
+	if err != nil {
+		respondServiceError(c, err, "查询安全审计日志失败")
+		return
+	}
+
+	utils.SuccessList(c, logs, utils.NewPagination(query.Page, query.PageSize, total))
+}
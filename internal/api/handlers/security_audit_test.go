@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/repository/models"
+	"cloudpan/internal/service/audit"
+)
+
+// recordingSecurityAudit 记录写入和查询条件的安全审计服务
+type recordingSecurityAudit struct {
+	events []*audit.Event
+	query  audit.SecurityQuery
+	logs   []*models.AuditLog
+	err    error
+}
+
+func (s *recordingSecurityAudit) Record(_ context.Context, events ...*audit.Event) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.events = append(s.events, events...)
+	return nil
+}
+
+func (s *recordingSecurityAudit) List(_ context.Context, query audit.SecurityQuery) ([]*models.AuditLog, int64, error) {
+	s.query = query
+	if s.err != nil {
+		return nil, 0, s.err
+	}
+	return s.logs, int64(len(s.logs)), nil
+}
+
+func TestAdminSecurityAuditHandler_ListLogs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	service := &recordingSecurityAudit{logs: []*models.AuditLog{{ID: 1, Action: audit.SecurityActionLogin}}}
+	router := gin.New()
+	router.GET("/admin/security-logs", NewAdminSecurityAuditHandler(service, zap.NewNop()).ListLogs)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet,
+		"/admin/security-logs?user_id=42&category=auth&result=failure&from=2024-05-01T00:00:00Z&page=3&page_size=500", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, audit.SecurityQuery{
+		UserID:   42,
+		Category: audit.SecurityCategoryAuth,
+		Result:   models.AuditResultFailure,
+		From:     time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		Page:     3,
+		PageSize: maxAuditPageSize,
+	}, service.query)
+	assert.Contains(t, w.Body.String(), `"total_count":1`)
+
+	for _, query := range []string{"user_id=abc", "to=yesterday"} {
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/security-logs?"+query, nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
+func TestRecordSecurity(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	service := &recordingSecurityAudit{}
+	var recorder securityAudit
+	recorder.SetSecurityAuditService(service)
+	router := gin.New()
+	router.POST("/action", func(c *gin.Context) {
+		c.Set("user_id", uint64(9))
+		c.Set("request_id", "req-1")
+		recorder.recordSecurity(c, zap.NewNop(), &audit.Event{Action: audit.SecurityActionPasswordChange})
+		c.Status(http.StatusNoContent)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/action", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	req.Header.Set("User-Agent", "browser")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	require.Len(t, service.events, 1)
+	event := service.events[0]
+	assert.Equal(t, uint(9), event.UserID) // 未指定时使用当前登录用户
+	assert.Equal(t, "192.0.2.1", event.IPAddress)
+	assert.Equal(t, "browser", event.UserAgent)
+	assert.Equal(t, "req-1", event.RequestID)
+	assert.Equal(t, http.MethodPost, event.RequestMethod)
+	assert.Equal(t, "/action", event.RequestURI)
+
+	// 写入失败不影响响应
+	service.err = errors.New("database unavailable")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/action", nil))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
+
+func TestShareCreationHandler_RecordsSecurityAudit(t *testing.T) {
+	service := new(MockCreationService)
+	service.On("Create", mock.Anything, uint(7), mock.Anything).
+		Return(&models.FileShare{FileID: 5, SharerID: 7, ShareCode: "abc", Permission: "download", HasPassword: true}, nil)
+	security := &recordingSecurityAudit{}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler := NewShareCreationHandler(service, zap.NewNop())
+	handler.SetSecurityAuditService(security)
+	router.POST("/shares", func(c *gin.Context) {
+		c.Set("user_id", uint64(7))
+		handler.CreateShare(c)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/shares", strings.NewReader(`{"file_id":5,"password":"1234"}`)))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Len(t, security.events, 1)
+	event := security.events[0]
+	assert.Equal(t, audit.SecurityActionShareCreate, event.Action)
+	assert.Equal(t, uint(7), event.UserID)
+	assert.Equal(t, uint(5), event.After["file_id"])
+	assert.Equal(t, true, event.After["has_password"])
+	assert.NotContains(t, event.After, "password") // 不记录分享密码
+}
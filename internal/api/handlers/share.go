@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/utils"
+	sharesvc "cloudpan/internal/service/share"
+)
+
+// ShareHandler 文件分享处理器
+type ShareHandler struct {
+	shareService sharesvc.ShareService
+	logger       *zap.Logger
+}
+
+// NewShareHandler 创建文件分享处理器
+func NewShareHandler(shareService sharesvc.ShareService, logger *zap.Logger) *ShareHandler {
+	return &ShareHandler{
+		shareService: shareService,
+		logger:       logger,
+	}
+}
+
+// VerifySharePasswordRequest 分享密码验证请求
+type VerifySharePasswordRequest struct {
+	Password string `json:"password"` // 分享密码，未设置密码的分享可为空
+}
+
+// SetSharePasswordRequest 设置分享密码请求
+type SetSharePasswordRequest struct {
+	Password string `json:"password"` // 新密码，为空表示取消密码
+}
+
+// VerifyPassword 验证分享密码
+//
+// @Summary 验证分享密码
+// @Description 访问者提交分享密码，验证通过后返回分享访问信息。同一IP对同一分享的尝试次数受限，连续错误过多时分享会被临时锁定
+// @Tags 分享
+// @Accept json
+// @Produce json
+// @Param code path string true "分享码"
+// @Param request body VerifySharePasswordRequest true "分享密码"
+// @Success 200 {object} utils.Response{data=share.ShareAccess} "验证通过"
+// @Failure 403 {object} utils.Response "分享密码错误"
+// @Failure 404 {object} utils.Response "分享不存在或已失效"
+// @Failure 429 {object} utils.Response "尝试过于频繁或分享已被临时锁定"
+// @Router /api/v1/public/shares/{code}/verify [post]
+func (h *ShareHandler) VerifyPassword(c *gin.Context) {
+	setNoStore(c)
+	var req VerifySharePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+		return
+	}
+
+	access, err := h.shareService.VerifyPassword(c.Request.Context(), c.Param("code"), req.Password, c.ClientIP())
+	if err != nil {
+		respondServiceError(c, err, "验证分享密码失败")
+		return
+	}
+
+	utils.Success(c, access)
+}
+
+// GetTransferStatus 查询分享流量状态
+//
+// @Summary 查询分享流量状态
+// @Description 返回分享本月下载流量上限、已用流量和恢复时间。exhausted为true时客户端应展示流量已用尽的提示页而不是下载按钮
+// @Tags 分享
+// @Produce json
+// @Param code path string true "分享码"
+// @Success 200 {object} utils.Response{data=share.TransferStatus} "流量状态"
+// @Failure 404 {object} utils.Response "分享不存在或已失效"
+// @Router /api/v1/public/shares/{code}/transfer [get]
+func (h *ShareHandler) GetTransferStatus(c *gin.Context) {
+	setNoStore(c)
+	status, err := h.shareService.GetTransferStatus(c.Request.Context(), c.Param("code"))
+	if err != nil {
+		respondServiceError(c, err, "查询分享流量失败")
+		return
+	}
+
+	utils.Success(c, status)
+}
+
+// SetPassword 设置或取消分享密码
+//
+// @Summary 设置分享密码
+// @Description 分享者设置或取消分享密码，密码以BCrypt哈希存储，设置后清除错误计数和锁定状态
+// @Tags 分享
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "分享ID"
+// @Param request body SetSharePasswordRequest true "分享密码"
+// @Success 200 {object} utils.Response "设置成功"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 403 {object} utils.Response "无权修改该分享"
+// @Failure 404 {object} utils.Response "分享不存在"
+// @Router /api/v1/shares/{id}/password [put]
+func (h *ShareHandler) SetPassword(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+	shareID, ok := parseIDParam(c, "id")
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "分享ID格式错误")
+		return
+	}
+
+	var req SetSharePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+		return
+	}
+
+	if err := h.shareService.SetPassword(c.Request.Context(), userID, shareID, req.Password); err != nil {
+		respondServiceError(c, err, "设置分享密码失败")
+		return
+	}
+
+	h.logger.Info("Share password updated",
+		zap.Uint("user_id", userID),
+		zap.Uint("share_id", shareID),
+		zap.String("ip", c.ClientIP()))
+	utils.Success(c, nil)
+}
+
+// RevokeShare 撤销分享
+//
+// @Summary 撤销分享
+// @Description 分享者撤销分享链接，之后访问者打开、下载分享均返回分享已失效，分享页的元数据缓存同时失效
+// @Tags 分享
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "分享ID"
+// @Success 200 {object} utils.Response "已撤销"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 403 {object} utils.Response "无权撤销该分享"
+// @Failure 404 {object} utils.Response "分享不存在"
+// @Router /api/v1/shares/{id} [delete]
+func (h *ShareHandler) RevokeShare(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+	shareID, ok := parseIDParam(c, "id")
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "分享ID格式错误")
+		return
+	}
+
+	if err := h.shareService.Revoke(c.Request.Context(), userID, shareID); err != nil {
+		respondServiceError(c, err, "撤销分享失败")
+		return
+	}
+
+	h.logger.Info("Share revoked",
+		zap.Uint("user_id", userID),
+		zap.Uint("share_id", shareID),
+		zap.String("ip", c.ClientIP()))
+	utils.SuccessWithMessage(c, "分享已撤销", nil)
+}
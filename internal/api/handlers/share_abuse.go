@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/utils"
+	sharesvc "cloudpan/internal/service/share"
+)
+
+// ReportShareRequest 举报分享请求
+type ReportShareRequest struct {
+	Reason       string `json:"reason" binding:"required"` // 原因类别：malware/phishing/copyright/illegal/spam/other
+	Details      string `json:"details"`                   // 补充说明，最多1000个字符
+	CaptchaToken string `json:"captcha_token"`             // 人机验证令牌，服务端启用举报人机验证时必填
+}
+
+// ReportShareResponse 举报分享响应
+type ReportShareResponse struct {
+	ReportID uint `json:"report_id"` // 举报ID
+}
+
+// ShareAbuseHandler 公开分享举报处理器
+type ShareAbuseHandler struct {
+	service sharesvc.AbuseService
+	logger  *zap.Logger
+}
+
+// NewShareAbuseHandler 创建公开分享举报处理器
+func NewShareAbuseHandler(service sharesvc.AbuseService, logger *zap.Logger) *ShareAbuseHandler {
+	return &ShareAbuseHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// ReportShare 举报公开分享
+//
+// @Summary 举报分享
+// @Description 访问者无需登录即可按原因类别举报分享，同一IP的举报次数受限，服务端启用时需要提交人机验证令牌。
+// @Description 同一IP对同一分享只能有一条待审核举报；待审核举报来自足够多的不同IP时分享被自动暂停，等待管理员审核
+// @Tags 分享
+// @Accept json
+// @Produce json
+// @Param code path string true "分享码"
+// @Param request body ReportShareRequest true "举报内容"
+// @Success 200 {object} utils.Response{data=ReportShareResponse} "举报已提交"
+// @Failure 400 {object} utils.Response "请求参数错误、需要人机验证或人机验证未通过"
+// @Failure 404 {object} utils.Response "分享不存在或已失效"
+// @Failure 409 {object} utils.Response "已举报过该分享，等待审核"
+// @Failure 429 {object} utils.Response "举报过于频繁"
+// @Router /api/v1/public/shares/{code}/report [post]
+func (h *ShareAbuseHandler) ReportShare(c *gin.Context) {
+	setNoStore(c)
+	var req ReportShareRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+		return
+	}
+
+	abuseReq := &sharesvc.AbuseReportRequest{
+		Code:         c.Param("code"),
+		Reason:       req.Reason,
+		Details:      req.Details,
+		CaptchaToken: req.CaptchaToken,
+		ClientIP:     c.ClientIP(),
+	}
+	if userID, ok := getCurrentUserID(c); ok {
+		abuseReq.ReporterID = &userID
+	}
+
+	report, err := h.service.Report(c.Request.Context(), abuseReq)
+	if err != nil {
+		respondServiceError(c, err, "提交举报失败")
+		return
+	}
+
+	utils.Success(c, &ReportShareResponse{ReportID: report.ID})
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/captcha"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+	sharesvc "cloudpan/internal/service/share"
+)
+
+// stubAbuseService 记录调用参数的分享举报服务
+type stubAbuseService struct {
+	request  *sharesvc.AbuseReportRequest
+	status   string
+	shareID  uint
+	pageSize int
+	adminID  uint
+	action   string
+	note     string
+}
+
+func (s *stubAbuseService) Report(_ context.Context, req *sharesvc.AbuseReportRequest) (*models.ShareAbuseReport, error) {
+	s.request = req
+	switch req.CaptchaToken {
+	case "":
+		return nil, captcha.ErrRequired
+	case "wrong":
+		return nil, captcha.ErrInvalid
+	}
+	if req.Code == "again" {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrResourceExists, "您已举报过该分享，请等待管理员审核")
+	}
+	report := &models.ShareAbuseReport{ShareCode: req.Code, Reason: req.Reason}
+	report.ID = 11
+	return report, nil
+}
+
+func (s *stubAbuseService) ListReports(_ context.Context, status string, shareID uint, page, pageSize int) ([]*models.ShareAbuseReport, int64, error) {
+	s.status, s.shareID, s.pageSize = status, shareID, pageSize
+	return []*models.ShareAbuseReport{{ShareID: 3, Reason: models.ShareAbuseReasonSpam}}, 1, nil
+}
+
+func (s *stubAbuseService) Resolve(_ context.Context, adminID, reportID uint, action, note string) (*sharesvc.AbuseResolution, error) {
+	s.adminID, s.action, s.note = adminID, action, note
+	if reportID != 1 {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "举报不存在")
+	}
+	return &sharesvc.AbuseResolution{ShareID: 3, ShareStatus: models.ShareStatusDisabled, Resolved: 2}, nil
+}
+
+func TestShareAbuseHandler_ReportShare(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	service := &stubAbuseService{}
+	router := gin.New()
+	router.POST("/public/shares/:code/report", func(c *gin.Context) {
+		if c.GetHeader("X-Test-User") != "" {
+			c.Set("user_id", uint64(5))
+		}
+	}, NewShareAbuseHandler(service, zap.NewNop()).ReportShare)
+
+	w := serveAdminEmail(router, http.MethodPost, "/public/shares/abc123/report", `{"reason":"phishing","details":"仿冒","captcha_token":"ok"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"report_id":11`)
+	assert.Equal(t, "abc123", service.request.Code)
+	assert.Equal(t, "仿冒", service.request.Details)
+	assert.Equal(t, "192.0.2.1", service.request.ClientIP)
+	assert.Nil(t, service.request.ReporterID, "未登录时没有举报人ID")
+
+	w = serveAdminEmail(router, http.MethodPost, "/public/shares/abc123/report", `{"reason":"spam"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, utils.CodeCaptchaRequired, decodeShareResponse(t, w).Code)
+
+	w = serveAdminEmail(router, http.MethodPost, "/public/shares/abc123/report", `{"reason":"spam","captcha_token":"wrong"}`)
+	assert.Equal(t, utils.CodeCaptchaWrong, decodeShareResponse(t, w).Code)
+
+	w = serveAdminEmail(router, http.MethodPost, "/public/shares/again/report", `{"reason":"spam","captcha_token":"ok"}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = serveAdminEmail(router, http.MethodPost, "/public/shares/abc123/report", `{}`)
+	assert.Equal(t, utils.CodeBadRequest, decodeShareResponse(t, w).Code)
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/utils"
+	sharesvc "cloudpan/internal/service/share"
+)
+
+// ShareAccessHandler 分享链接访问处理器
+type ShareAccessHandler struct {
+	accessService   sharesvc.AccessService
+	downloadEnabled bool
+	logger          *zap.Logger
+}
+
+// NewShareAccessHandler 创建分享链接访问处理器
+//
+// downloadEnabled 为false时(如存储不可用)不返回下载地址
+func NewShareAccessHandler(accessService sharesvc.AccessService, downloadEnabled bool, logger *zap.Logger) *ShareAccessHandler {
+	return &ShareAccessHandler{
+		accessService:   accessService,
+		downloadEnabled: downloadEnabled,
+		logger:          logger,
+	}
+}
+
+// Resolve 打开分享链接
+//
+// @Summary 打开分享链接
+// @Description 访问者打开分享链接，返回分享信息、文件信息和下载地址。分享设置了密码时需在请求头中携带密码；每次成功打开占用一次访问次数，达到最大访问次数后分享失效
+// @Tags 分享
+// @Produce json
+// @Param code path string true "分享码"
+// @Param X-Share-Password header string false "分享密码"
+// @Success 200 {object} utils.Response{data=share.ShareInfo} "分享信息"
+// @Failure 403 {object} utils.Response "分享密码错误"
+// @Failure 404 {object} utils.Response "分享不存在、已失效或访问次数已达上限"
+// @Failure 429 {object} utils.Response "尝试过于频繁或分享已被临时锁定"
+// @Router /api/v1/public/shares/{code} [get]
+func (h *ShareAccessHandler) Resolve(c *gin.Context) {
+	// 每次打开都占用访问次数，不能由浏览器或CDN缓存
+	setNoStore(c)
+	info, err := h.accessService.Resolve(c.Request.Context(), c.Param("code"), c.GetHeader(SharePasswordHeader), c.ClientIP())
+	if err != nil {
+		respondServiceError(c, err, "打开分享失败")
+		return
+	}
+
+	if info.Downloadable && h.downloadEnabled {
+		info.DownloadURL = strings.TrimSuffix(c.Request.URL.Path, "/") + "/download"
+	} else {
+		info.Downloadable = false
+	}
+
+	h.logger.Info("Share accessed",
+		zap.Uint("share_id", info.ShareID),
+		zap.Uint("file_id", info.File.ID),
+		zap.String("ip", c.ClientIP()))
+	utils.Success(c, info)
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/utils"
+	sharesvc "cloudpan/internal/service/share"
+)
+
+// MockShareAccessService 模拟分享链接访问服务
+type MockShareAccessService struct {
+	mock.Mock
+}
+
+func (m *MockShareAccessService) Resolve(ctx context.Context, code, password, clientIP string) (*sharesvc.ShareInfo, error) {
+	args := m.Called(ctx, code, password, clientIP)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*sharesvc.ShareInfo), args.Error(1)
+}
+
+func (m *MockShareAccessService) ConsumeDownload(ctx context.Context, shareID uint) error {
+	return m.Called(ctx, shareID).Error(0)
+}
+
+func setupShareAccessRouter(service *MockShareAccessService, downloadEnabled bool) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler := NewShareAccessHandler(service, downloadEnabled, zap.NewNop())
+	router.GET("/api/v1/public/shares/:code", handler.Resolve)
+	return router
+}
+
+func TestShareAccessHandler_Resolve(t *testing.T) {
+	newInfo := func() *sharesvc.ShareInfo {
+		return &sharesvc.ShareInfo{
+			ShareAccess:  sharesvc.ShareAccess{ShareID: 3, ShareCode: "abc123", FileID: 10, Permission: "download"},
+			Downloadable: true,
+			File:         sharesvc.SharedFile{ID: 10, Name: "a.txt", Size: 5},
+		}
+	}
+
+	t.Run("success with download url", func(t *testing.T) {
+		service := new(MockShareAccessService)
+		service.On("Resolve", mock.Anything, "abc123", "secret", "192.0.2.1").Return(newInfo(), nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/public/shares/abc123", nil)
+		req.Header.Set(SharePasswordHeader, "secret")
+		req.RemoteAddr = "192.0.2.1:1234"
+		w := httptest.NewRecorder()
+		setupShareAccessRouter(service, true).ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Data sharesvc.ShareInfo `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.True(t, resp.Data.Downloadable)
+		assert.Equal(t, "/api/v1/public/shares/abc123/download", resp.Data.DownloadURL)
+		assert.Equal(t, "a.txt", resp.Data.File.Name)
+	})
+
+	t.Run("download disabled", func(t *testing.T) {
+		service := new(MockShareAccessService)
+		service.On("Resolve", mock.Anything, "abc123", "", mock.Anything).Return(newInfo(), nil)
+
+		w := httptest.NewRecorder()
+		setupShareAccessRouter(service, false).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/public/shares/abc123", nil))
+
+		require.Equal(t, http.StatusOK, w.Code)
+		data := decodeShareResponse(t, w).Data.(map[string]interface{})
+		assert.Equal(t, false, data["downloadable"])
+		assert.NotContains(t, data, "download_url")
+	})
+
+	t.Run("access limit reached", func(t *testing.T) {
+		service := new(MockShareAccessService)
+		service.On("Resolve", mock.Anything, "abc123", "", mock.Anything).
+			Return(nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "分享访问次数已达上限"))
+
+		w := httptest.NewRecorder()
+		setupShareAccessRouter(service, true).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/public/shares/abc123", nil))
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Equal(t, utils.CodeNotFound, decodeShareResponse(t, w).Code)
+	})
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+	"cloudpan/internal/service/audit"
+	sharesvc "cloudpan/internal/service/share"
+)
+
+// ShareCreationHandler 分享创建处理器
+type ShareCreationHandler struct {
+	fileAccess
+	securityAudit
+	service    sharesvc.CreationService
+	templates  sharesvc.TemplateService
+	moderation sharesvc.ContentModerator
+	logger     *zap.Logger
+}
+
+// NewShareCreationHandler 创建分享创建处理器
+func NewShareCreationHandler(service sharesvc.CreationService, logger *zap.Logger) *ShareCreationHandler {
+	return &ShareCreationHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// SetTemplateService 设置分享模板服务，未设置时不应用模板
+func (h *ShareCreationHandler) SetTemplateService(templates sharesvc.TemplateService) {
+	h.templates = templates
+}
+
+// SetContentModerator 设置分享前的内容审核，未设置时不审核
+func (h *ShareCreationHandler) SetContentModerator(moderation sharesvc.ContentModerator) {
+	h.moderation = moderation
+}
+
+// CreateShare 创建分享链接
+//
+// @Summary 创建分享
+// @Description 为自己的文件或文件夹(或获得share授权的他人文件，分享归文件所有者)创建分享链接，可设置权限(view/download)、密码、有效天数和最大访问/下载次数。分享链接为公开分享接口路径加分享码
+// @Description 请求中未设置的选项取template指定的分享模板或当前用户的默认模板，no_template为true时不应用默认模板；模板要求密码时必须提供password
+// @Description 启用内容审核时分享图片前先审核图片内容，判定违规或经管理员复核禁止的图片不能分享
+// @Tags 分享
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body share.CreateShareRequest true "分享设置"
+// @Success 200 {object} utils.Response{data=models.FileShare} "创建的分享"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "无权分享该文件、文件当前不可分享或图片内容违规"
+// @Failure 404 {object} utils.Response "文件或分享模板不存在"
+// @Router /api/v1/shares [post]
+func (h *ShareCreationHandler) CreateShare(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	var req sharesvc.CreateShareRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+		return
+	}
+
+	actingUserID, ok := h.actingUser(c, userID, req.FileID, models.FilePermissionShare)
+	if !ok {
+		return
+	}
+	if h.templates != nil {
+		// 模板属于发起分享的用户，代他人分享时也使用自己的模板
+		if err := h.templates.Apply(c.Request.Context(), userID, &req); err != nil {
+			respondServiceError(c, err, "应用分享模板失败")
+			return
+		}
+	}
+
+	if h.moderation != nil {
+		if err := h.moderation.Check(c.Request.Context(), req.FileID); err != nil {
+			respondServiceError(c, err, "内容审核失败")
+			return
+		}
+	}
+
+	share, err := h.service.Create(c.Request.Context(), actingUserID, &req)
+	if err != nil {
+		respondServiceError(c, err, "创建分享失败")
+		return
+	}
+
+	h.logger.Info("Share created",
+		zap.Uint("user_id", userID),
+		zap.Uint("share_id", share.ID),
+		zap.Uint("file_id", share.FileID),
+		zap.String("ip", c.ClientIP()))
+	h.recordSecurity(c, h.logger, &audit.Event{
+		UserID:       userID,
+		Action:       audit.SecurityActionShareCreate,
+		ResourceType: audit.ResourceShare,
+		ResourceID:   strconv.FormatUint(uint64(share.ID), 10),
+		After: map[string]interface{}{
+			"file_id":      share.FileID,
+			"sharer_id":    share.SharerID,
+			"share_code":   share.ShareCode,
+			"permission":   share.Permission,
+			"has_password": share.HasPassword,
+			"max_access":   share.MaxAccess,
+			"max_download": share.MaxDownload,
+			"expires_at":   share.ExpiresAt,
+		},
+	})
+	utils.Success(c, share)
+}
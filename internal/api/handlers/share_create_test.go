@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+	sharesvc "cloudpan/internal/service/share"
+)
+
+// MockCreationService 模拟分享创建服务
+type MockCreationService struct {
+	mock.Mock
+}
+
+func (m *MockCreationService) Create(ctx context.Context, userID uint, req *sharesvc.CreateShareRequest) (*models.FileShare, error) {
+	args := m.Called(ctx, userID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.FileShare), args.Error(1)
+}
+
+func setupShareCreationRouter(service *MockCreationService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler := NewShareCreationHandler(service, zap.NewNop())
+	router.POST("/shares", func(c *gin.Context) {
+		c.Set("user_id", uint64(7))
+		handler.CreateShare(c)
+	})
+	return router
+}
+
+func TestShareCreationHandler_CreateShare(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		service := new(MockCreationService)
+		service.On("Create", mock.Anything, uint(7), mock.MatchedBy(func(req *sharesvc.CreateShareRequest) bool {
+			return req.FileID == 5 && req.Password == "1234" && req.ExpireDays == 7
+		})).Return(&models.FileShare{FileID: 5, ShareCode: "abc", ShareURL: "/api/v1/public/shares/abc"}, nil)
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/shares", strings.NewReader(`{"file_id":5,"password":"1234","expire_days":7}`))
+		setupShareCreationRouter(service).ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"share_code":"abc"`)
+		service.AssertExpectations(t)
+	})
+
+	t.Run("other user's file", func(t *testing.T) {
+		service := new(MockCreationService)
+		service.On("Create", mock.Anything, uint(7), mock.Anything).
+			Return(nil, pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, "只能分享自己的文件"))
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/shares", strings.NewReader(`{"file_id":5}`))
+		setupShareCreationRouter(service).ServeHTTP(w, req)
+
+		assert.Equal(t, utils.CodeForbidden, decodeShareResponse(t, w).Code)
+	})
+
+	t.Run("content moderation", func(t *testing.T) {
+		service := new(MockCreationService)
+		service.On("Create", mock.Anything, uint(7), mock.Anything).Return(&models.FileShare{FileID: 5, ShareCode: "abc"}, nil)
+		moderation := &stubModerationService{}
+		handler := NewShareCreationHandler(service, zap.NewNop())
+		handler.SetContentModerator(moderation)
+		router := gin.New()
+		router.POST("/shares", func(c *gin.Context) {
+			c.Set("user_id", uint64(7))
+			handler.CreateShare(c)
+		})
+
+		w := serveAdminEmail(router, http.MethodPost, "/shares", `{"file_id":13}`)
+		assert.Equal(t, http.StatusForbidden, w.Code, "违规图片不能分享")
+		service.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+
+		w = serveAdminEmail(router, http.MethodPost, "/shares", `{"file_id":5}`)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, []uint{13, 5}, moderation.checked)
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/shares", strings.NewReader(`{}`))
+		setupShareCreationRouter(new(MockCreationService)).ServeHTTP(w, req)
+
+		assert.Equal(t, utils.CodeBadRequest, decodeShareResponse(t, w).Code)
+	})
+}
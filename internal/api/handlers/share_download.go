@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"io"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/utils"
+	sharesvc "cloudpan/internal/service/share"
+)
+
+// SharePasswordHeader 下载时携带分享密码的请求头
+const SharePasswordHeader = "X-Share-Password"
+
+// ImageLocationStrippedHeader 响应头，标识返回的图片是否已去除位置信息
+const ImageLocationStrippedHeader = "X-Image-Location-Stripped"
+
+// ShareDownloadHandler 分享下载处理器
+type ShareDownloadHandler struct {
+	downloadService sharesvc.DownloadService
+	logger          *zap.Logger
+}
+
+// NewShareDownloadHandler 创建分享下载处理器
+func NewShareDownloadHandler(downloadService sharesvc.DownloadService, logger *zap.Logger) *ShareDownloadHandler {
+	return &ShareDownloadHandler{
+		downloadService: downloadService,
+		logger:          logger,
+	}
+}
+
+// SharePrivacyRequest 分享隐私设置请求
+type SharePrivacyRequest struct {
+	StripImageLocation *bool `json:"strip_image_location"` // 下载图片时是否去除位置信息，null表示恢复默认
+}
+
+// Download 通过分享链接下载文件
+//
+// @Summary 下载分享文件
+// @Description 访问者通过分享链接下载文件，分享设置了密码时需在请求头中携带密码。启用位置去除时，JPEG/PNG图片返回去除GPS和XMP元数据的副本，其他格式原样返回
+// @Tags 分享
+// @Produce octet-stream
+// @Param code path string true "分享码"
+// @Param X-Share-Password header string false "分享密码"
+// @Success 200 {file} file "文件内容"
+// @Failure 403 {object} utils.Response "分享密码错误、不允许下载或流量已用尽"
+// @Failure 404 {object} utils.Response "分享不存在或已失效"
+// @Failure 429 {object} utils.Response "尝试过于频繁或分享已被临时锁定"
+// @Router /api/v1/public/shares/{code}/download [get]
+func (h *ShareDownloadHandler) Download(c *gin.Context) {
+	ctx := c.Request.Context()
+	code := c.Param("code")
+
+	download, err := h.downloadService.Open(ctx, code, c.GetHeader(SharePasswordHeader), c.ClientIP())
+	if err != nil {
+		respondServiceError(c, err, "下载分享文件失败")
+		return
+	}
+	defer download.Content.Close()
+
+	c.Header("Content-Type", download.MimeType)
+	c.Header("Content-Disposition", contentDisposition(download.Name))
+	c.Header("Content-Length", strconv.FormatInt(download.Size, 10))
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header(ImageLocationStrippedHeader, strconv.FormatBool(download.Sanitized))
+	c.Status(200)
+
+	// 响应头已发出，之后的错误只能记录日志并中断连接；中断前已传输的流量同样计入
+	written, err := io.Copy(c.Writer, download.Content)
+	h.downloadService.Finish(ctx, download, written)
+	if err != nil {
+		h.logger.Warn("Share download interrupted",
+			zap.Uint("share_id", download.ShareID),
+			zap.Int64("bytes", written),
+			zap.Error(err),
+			zap.String("ip", c.ClientIP()))
+		c.Abort()
+		return
+	}
+
+	h.logger.Info("Share downloaded",
+		zap.Uint("share_id", download.ShareID),
+		zap.Uint("file_id", download.FileID),
+		zap.Int64("bytes", written),
+		zap.Bool("location_stripped", download.Sanitized),
+		zap.String("ip", c.ClientIP()))
+}
+
+// GetUserPrivacy 查询分享默认隐私设置
+//
+// @Summary 查询分享默认隐私设置
+// @Description 返回当前用户所有分享的默认隐私设置及其来源(user表示用户设置，system表示系统配置)
+// @Tags 分享
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response{data=share.PrivacySettings} "隐私设置"
+// @Router /api/v1/shares/privacy [get]
+func (h *ShareDownloadHandler) GetUserPrivacy(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	settings, err := h.downloadService.GetUserPrivacy(c.Request.Context(), userID)
+	if err != nil {
+		respondServiceError(c, err, "查询分享隐私设置失败")
+		return
+	}
+
+	utils.Success(c, settings)
+}
+
+// SetUserPrivacy 设置分享默认隐私设置
+//
+// @Summary 设置分享默认隐私设置
+// @Description 设置当前用户所有分享下载图片时是否去除位置信息，strip_image_location为null时恢复为系统配置。单个分享的设置优先
+// @Tags 分享
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body SharePrivacyRequest true "隐私设置"
+// @Success 200 {object} utils.Response{data=share.PrivacySettings} "生效的隐私设置"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Router /api/v1/shares/privacy [put]
+func (h *ShareDownloadHandler) SetUserPrivacy(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	var req SharePrivacyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+		return
+	}
+
+	settings, err := h.downloadService.SetUserPrivacy(c.Request.Context(), userID, req.StripImageLocation)
+	if err != nil {
+		respondServiceError(c, err, "设置分享隐私失败")
+		return
+	}
+
+	h.logger.Info("Share privacy default updated",
+		zap.Uint("user_id", userID),
+		zap.Bool("strip_image_location", settings.StripImageLocation),
+		zap.String("source", settings.Source),
+		zap.String("ip", c.ClientIP()))
+	utils.Success(c, settings)
+}
+
+// SetSharePrivacy 设置单个分享的隐私设置
+//
+// @Summary 设置分享隐私
+// @Description 分享者设置该分享下载图片时是否去除位置信息，strip_image_location为null时沿用分享者的默认设置
+// @Tags 分享
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "分享ID"
+// @Param request body SharePrivacyRequest true "隐私设置"
+// @Success 200 {object} utils.Response{data=share.PrivacySettings} "生效的隐私设置"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 403 {object} utils.Response "无权修改该分享"
+// @Failure 404 {object} utils.Response "分享不存在"
+// @Router /api/v1/shares/{id}/privacy [put]
+func (h *ShareDownloadHandler) SetSharePrivacy(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+	shareID, ok := parseIDParam(c, "id")
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "分享ID格式错误")
+		return
+	}
+
+	var req SharePrivacyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+		return
+	}
+
+	settings, err := h.downloadService.SetSharePrivacy(c.Request.Context(), userID, shareID, req.StripImageLocation)
+	if err != nil {
+		respondServiceError(c, err, "设置分享隐私失败")
+		return
+	}
+
+	h.logger.Info("Share privacy updated",
+		zap.Uint("user_id", userID),
+		zap.Uint("share_id", shareID),
+		zap.Bool("strip_image_location", settings.StripImageLocation),
+		zap.String("source", settings.Source),
+		zap.String("ip", c.ClientIP()))
+	utils.Success(c, settings)
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/utils"
+	sharesvc "cloudpan/internal/service/share"
+)
+
+// MockDownloadService 模拟分享下载服务
+type MockDownloadService struct {
+	mock.Mock
+}
+
+func (m *MockDownloadService) Open(ctx context.Context, code, password, clientIP string) (*sharesvc.Download, error) {
+	args := m.Called(ctx, code, password, clientIP)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*sharesvc.Download), args.Error(1)
+}
+
+func (m *MockDownloadService) Finish(ctx context.Context, download *sharesvc.Download, written int64) {
+	m.Called(ctx, download, written)
+}
+
+func (m *MockDownloadService) GetUserPrivacy(ctx context.Context, userID uint) (*sharesvc.PrivacySettings, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*sharesvc.PrivacySettings), args.Error(1)
+}
+
+func (m *MockDownloadService) SetUserPrivacy(ctx context.Context, userID uint, strip *bool) (*sharesvc.PrivacySettings, error) {
+	args := m.Called(ctx, userID, strip)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*sharesvc.PrivacySettings), args.Error(1)
+}
+
+func (m *MockDownloadService) SetSharePrivacy(ctx context.Context, userID, shareID uint, strip *bool) (*sharesvc.PrivacySettings, error) {
+	args := m.Called(ctx, userID, shareID, strip)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*sharesvc.PrivacySettings), args.Error(1)
+}
+
+func setupShareDownloadRouter(service *MockDownloadService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler := NewShareDownloadHandler(service, zap.NewNop())
+	router.GET("/public/shares/:code/download", handler.Download)
+	authed := router.Group("/shares", func(c *gin.Context) {
+		c.Set("user_id", uint64(7))
+	})
+	authed.GET("/privacy", handler.GetUserPrivacy)
+	authed.PUT("/privacy", handler.SetUserPrivacy)
+	authed.PUT("/:id/privacy", handler.SetSharePrivacy)
+	return router
+}
+
+func TestShareDownloadHandler_Download(t *testing.T) {
+	t.Run("streams sanitized image", func(t *testing.T) {
+		service := new(MockDownloadService)
+		download := &sharesvc.Download{
+			ShareID:   3,
+			FileID:    9,
+			Name:      "假期.jpg",
+			MimeType:  "image/jpeg",
+			Size:      5,
+			Sanitized: true,
+			Content:   io.NopCloser(strings.NewReader("image")),
+		}
+		service.On("Open", mock.Anything, "abc", "secret", mock.Anything).Return(download, nil)
+		service.On("Finish", mock.Anything, download, int64(5)).Return()
+
+		req := httptest.NewRequest(http.MethodGet, "/public/shares/abc/download", nil)
+		req.Header.Set(SharePasswordHeader, "secret")
+		w := httptest.NewRecorder()
+		setupShareDownloadRouter(service).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "image", w.Body.String())
+		assert.Equal(t, "image/jpeg", w.Header().Get("Content-Type"))
+		assert.Equal(t, "5", w.Header().Get("Content-Length"))
+		assert.Equal(t, "true", w.Header().Get(ImageLocationStrippedHeader))
+		assert.Contains(t, w.Header().Get("Content-Disposition"), "filename*=UTF-8''%E5%81%87%E6%9C%9F.jpg")
+		service.AssertExpectations(t)
+	})
+
+	t.Run("service error", func(t *testing.T) {
+		service := new(MockDownloadService)
+		service.On("Open", mock.Anything, "abc", "", mock.Anything).
+			Return(nil, pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "图片无法去除位置信息，已阻止下载"))
+
+		w := httptest.NewRecorder()
+		setupShareDownloadRouter(service).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/public/shares/abc/download", nil))
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Equal(t, utils.CodeForbidden, decodeShareResponse(t, w).Code)
+		service.AssertNotCalled(t, "Finish", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestShareDownloadHandler_Privacy(t *testing.T) {
+	t.Run("set share privacy", func(t *testing.T) {
+		service := new(MockDownloadService)
+		service.On("SetSharePrivacy", mock.Anything, uint(7), uint(3), mock.MatchedBy(func(strip *bool) bool {
+			return strip != nil && !*strip
+		})).Return(&sharesvc.PrivacySettings{StripImageLocation: false, Source: sharesvc.PrivacySourceShare}, nil)
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, "/shares/3/privacy", strings.NewReader(`{"strip_image_location":false}`))
+		req.Header.Set("Content-Type", "application/json")
+		setupShareDownloadRouter(service).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		service.AssertExpectations(t)
+	})
+
+	t.Run("reset user default", func(t *testing.T) {
+		service := new(MockDownloadService)
+		service.On("SetUserPrivacy", mock.Anything, uint(7), (*bool)(nil)).
+			Return(&sharesvc.PrivacySettings{StripImageLocation: true, Source: sharesvc.PrivacySourceSystem}, nil)
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, "/shares/privacy", strings.NewReader(`{"strip_image_location":null}`))
+		req.Header.Set("Content-Type", "application/json")
+		setupShareDownloadRouter(service).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		service.AssertExpectations(t)
+	})
+
+	t.Run("invalid share id", func(t *testing.T) {
+		service := new(MockDownloadService)
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, "/shares/abc/privacy", strings.NewReader(`{}`))
+		setupShareDownloadRouter(service).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/service/file"
+	sharesvc "cloudpan/internal/service/share"
+)
+
+// shareMetadataMaxAge 分享元数据允许浏览器和CDN缓存的最长时间
+//
+// 分享更新或撤销后Redis缓存立即失效，HTTP缓存最多在该时间内仍返回旧数据
+const shareMetadataMaxAge = time.Minute
+
+// ShareThumbnailOpener 打开文件缩略图，由 file.PreviewService 实现
+type ShareThumbnailOpener interface {
+	Open(ctx context.Context, userID, fileID uint, kind string) (*file.FilePreview, error)
+}
+
+// ShareMetadataHandler 分享公开元数据处理器
+type ShareMetadataHandler struct {
+	service  sharesvc.MetadataService
+	previews ShareThumbnailOpener
+	logger   *zap.Logger
+	now      func() time.Time
+}
+
+// NewShareMetadataHandler 创建分享公开元数据处理器
+func NewShareMetadataHandler(service sharesvc.MetadataService, logger *zap.Logger) *ShareMetadataHandler {
+	return &ShareMetadataHandler{
+		service: service,
+		logger:  logger,
+		now:     time.Now,
+	}
+}
+
+// SetPreviewService 设置预览服务，未设置时分享缩略图接口返回404
+func (h *ShareMetadataHandler) SetPreviewService(previews ShareThumbnailOpener) {
+	h.previews = previews
+}
+
+// GetMetadata 获取分享元数据
+//
+// @Summary 获取分享元数据
+// @Description 分享页加载时获取分享的权限、是否需要密码、过期时间、文件信息、缩略图地址、分享者显示名称和Open Graph标签数据，不占用访问次数。
+// @Description 设置了密码的分享不返回文件信息和缩略图，设置了隐藏分享者的分享不返回分享者。
+// @Description 响应可被浏览器和CDN缓存最多60秒(不超过分享过期时间)，并带有ETag，携带 If-None-Match 请求且内容未变化时返回304
+// @Tags 分享
+// @Produce json
+// @Param code path string true "分享码"
+// @Param If-None-Match header string false "上次响应的ETag"
+// @Success 200 {object} utils.Response{data=share.ShareMetadata} "分享元数据"
+// @Success 304 "内容未变化"
+// @Failure 404 {object} utils.Response "分享不存在或已失效"
+// @Router /api/v1/public/shares/{code}/meta [get]
+// @Router /api/v1/s/{code}/meta [get]
+func (h *ShareMetadataHandler) GetMetadata(c *gin.Context) {
+	meta, err := h.service.Get(c.Request.Context(), c.Param("code"))
+	if err != nil {
+		setNoStore(c)
+		respondServiceError(c, err, "获取分享信息失败")
+		return
+	}
+
+	etag := shareMetadataETag(meta)
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", h.cacheControl(meta))
+	if ifNoneMatch(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	utils.Success(c, meta)
+}
+
+// GetThumbnail 获取分享文件的缩略图
+//
+// @Summary 获取分享缩略图
+// @Description 返回分享文件的缩略图，用于分享页和社交平台预览，不占用访问次数。
+// @Description 分享设置了密码、文件是文件夹或尚未生成缩略图时返回404；响应可被共享缓存最多60秒
+// @Tags 分享
+// @Produce jpeg
+// @Param code path string true "分享码"
+// @Param If-None-Match header string false "上次响应的ETag"
+// @Success 200 {file} file "缩略图"
+// @Success 304 "内容未变化"
+// @Failure 404 {object} utils.Response "分享不存在、已失效或没有缩略图"
+// @Router /api/v1/public/shares/{code}/thumbnail [get]
+// @Router /api/v1/s/{code}/thumbnail [get]
+func (h *ShareMetadataHandler) GetThumbnail(c *gin.Context) {
+	ctx := c.Request.Context()
+	if h.previews == nil {
+		utils.ErrorWithMessage(c, utils.CodeNotFound, "分享没有缩略图")
+		return
+	}
+
+	shared, err := h.service.ThumbnailFile(ctx, c.Param("code"))
+	if err != nil {
+		setNoStore(c)
+		respondServiceError(c, err, "获取分享缩略图失败")
+		return
+	}
+
+	// 缩略图尚未生成或已失效时按没有缩略图处理，不向访问者暴露预览状态
+	preview, err := h.previews.Open(ctx, shared.UserID, shared.ID, file.PreviewKindThumbnail)
+	if err != nil {
+		h.logger.Warn("Failed to open share thumbnail",
+			zap.Uint("file_id", shared.ID),
+			zap.Error(err))
+		setNoStore(c)
+		if errors.Is(err, file.ErrPreviewPending) || pkgErrors.IsNotFoundError(err) || pkgErrors.IsPermissionError(err) {
+			utils.ErrorWithMessage(c, utils.CodeNotFound, "分享没有缩略图")
+			return
+		}
+		respondServiceError(c, err, "获取分享缩略图失败")
+		return
+	}
+	defer preview.Content.Close()
+
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(shareMetadataMaxAge/time.Second)))
+	c.Header("ETag", preview.ETag)
+	c.Header("X-Content-Type-Options", "nosniff")
+	if ifNoneMatch(c.GetHeader("If-None-Match"), preview.ETag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.DataFromReader(http.StatusOK, preview.Size, preview.MimeType, preview.Content, nil)
+}
+
+// cacheControl 计算元数据响应的缓存策略，有效期不超过分享过期时间
+func (h *ShareMetadataHandler) cacheControl(meta *sharesvc.ShareMetadata) string {
+	maxAge := shareMetadataMaxAge
+	if meta.ExpiresAt != nil {
+		maxAge = min(maxAge, meta.ExpiresAt.Sub(h.now()))
+	}
+	if maxAge < time.Second {
+		return "no-cache"
+	}
+	return fmt.Sprintf("public, max-age=%d", int(maxAge/time.Second))
+}
+
+// shareMetadataETag 根据元数据内容生成强ETag
+func shareMetadataETag(meta *sharesvc.ShareMetadata) string {
+	data, _ := json.Marshal(meta)
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// ifNoneMatch 判断 If-None-Match 请求头是否匹配ETag，支持多个值和弱比较
+func ifNoneMatch(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// setNoStore 禁止缓存响应，用于占用访问次数或携带密码的分享接口
+func setNoStore(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/repository/models"
+	"cloudpan/internal/service/file"
+	sharesvc "cloudpan/internal/service/share"
+)
+
+// stubMetadataService 返回固定元数据的分享元数据服务
+type stubMetadataService struct {
+	meta  *sharesvc.ShareMetadata
+	err   error
+	thumb *models.File
+}
+
+func (s *stubMetadataService) Get(context.Context, string) (*sharesvc.ShareMetadata, error) {
+	return s.meta, s.err
+}
+
+func (s *stubMetadataService) ThumbnailFile(context.Context, string) (*models.File, error) {
+	if s.thumb == nil {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "分享没有缩略图")
+	}
+	return s.thumb, nil
+}
+
+func (s *stubMetadataService) Invalidate(context.Context, string) error {
+	return nil
+}
+
+// stubThumbnailOpener 返回固定内容的缩略图
+type stubThumbnailOpener struct {
+	err    error
+	userID uint
+}
+
+func (s *stubThumbnailOpener) Open(_ context.Context, userID, fileID uint, kind string) (*file.FilePreview, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	s.userID = userID
+	return &file.FilePreview{
+		FileID:   fileID,
+		Kind:     kind,
+		MimeType: "image/jpeg",
+		Size:     4,
+		ETag:     `"thumb"`,
+		Content:  io.NopCloser(strings.NewReader("jpeg")),
+	}, nil
+}
+
+func TestShareMetadataHandler_GetMetadata(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	service := &stubMetadataService{meta: &sharesvc.ShareMetadata{ShareCode: "abc123", Permission: "download", Downloadable: true}}
+	handler := NewShareMetadataHandler(service, zap.NewNop())
+	handler.now = func() time.Time { return now }
+	router := gin.New()
+	router.GET("/public/shares/:code/meta", handler.GetMetadata)
+
+	serve := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/public/shares/abc123/meta", nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve("")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "public, max-age=60", w.Header().Get("Cache-Control"))
+	etag := w.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	t.Run("not modified", func(t *testing.T) {
+		w := serve(`"other", W/` + etag)
+		assert.Equal(t, http.StatusNotModified, w.Code)
+		assert.Empty(t, w.Body.String())
+	})
+
+	t.Run("changed content gets new etag", func(t *testing.T) {
+		service.meta = &sharesvc.ShareMetadata{ShareCode: "abc123", Permission: "view"}
+		w := serve(etag)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.NotEqual(t, etag, w.Header().Get("ETag"))
+	})
+
+	t.Run("max age capped by expiry", func(t *testing.T) {
+		expiresAt := now.Add(20 * time.Second)
+		service.meta = &sharesvc.ShareMetadata{ShareCode: "abc123", ExpiresAt: &expiresAt}
+		assert.Equal(t, "public, max-age=20", serve("").Header().Get("Cache-Control"))
+	})
+
+	t.Run("not found is not cached", func(t *testing.T) {
+		service.err = pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "分享已失效")
+		w := serve("")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	})
+}
+
+func TestShareMetadataHandler_GetThumbnail(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	shared := &models.File{UserID: 7, Name: "photo.jpg"}
+	shared.ID = 3
+	service := &stubMetadataService{thumb: shared}
+	previews := &stubThumbnailOpener{}
+	handler := NewShareMetadataHandler(service, zap.NewNop())
+	router := gin.New()
+	router.GET("/s/:code/thumbnail", handler.GetThumbnail)
+	serve := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/s/abc123/thumbnail", nil))
+		return w
+	}
+
+	// 未设置预览服务
+	assert.Equal(t, http.StatusNotFound, serve().Code)
+
+	handler.SetPreviewService(previews)
+	w := serve()
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "jpeg", w.Body.String())
+	assert.Equal(t, "image/jpeg", w.Header().Get("Content-Type"))
+	assert.Equal(t, "public, max-age=60", w.Header().Get("Cache-Control"))
+	// 以文件所有者的身份读取缩略图
+	assert.Equal(t, uint(7), previews.userID)
+
+	// 预览尚未生成时按没有缩略图处理
+	previews.err = file.ErrPreviewPending
+	w = serve()
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+
+	// 设置了密码的分享没有缩略图
+	previews.err = nil
+	service.thumb = nil
+	assert.Equal(t, http.StatusNotFound, serve().Code)
+}
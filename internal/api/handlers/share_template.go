@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/utils"
+	sharesvc "cloudpan/internal/service/share"
+)
+
+// ShareTemplateHandler 分享模板管理处理器
+type ShareTemplateHandler struct {
+	service sharesvc.TemplateService
+	logger  *zap.Logger
+}
+
+// NewShareTemplateHandler 创建分享模板管理处理器
+func NewShareTemplateHandler(service sharesvc.TemplateService, logger *zap.Logger) *ShareTemplateHandler {
+	return &ShareTemplateHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// SaveShareTemplateRequest 保存分享模板请求
+type SaveShareTemplateRequest struct {
+	Permission       string `json:"permission"`        // 权限类型(view/download)，为空使用系统默认
+	PasswordRequired bool   `json:"password_required"` // 是否要求设置分享密码
+	ExpireDays       int    `json:"expire_days"`       // 有效天数，0表示永久有效
+	MaxAccess        *int   `json:"max_access"`        // 最大访问次数，为空表示不限制
+	MaxDownload      *int   `json:"max_download"`      // 最大下载次数，为空表示不限制
+	IsDefault        bool   `json:"is_default"`        // 是否设为默认模板
+}
+
+// ListTemplates 查询分享模板
+//
+// @Summary 查询分享模板
+// @Description 按名称列出当前用户保存的分享模板，is_default标记默认模板
+// @Tags 分享
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response{data=[]share.ShareTemplate} "分享模板"
+// @Failure 401 {object} utils.Response "未认证"
+// @Router /api/v1/shares/templates [get]
+func (h *ShareTemplateHandler) ListTemplates(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	templates, err := h.service.List(c.Request.Context(), userID)
+	if err != nil {
+		respondServiceError(c, err, "查询分享模板失败")
+		return
+	}
+
+	utils.Success(c, templates)
+}
+
+// SaveTemplate 保存分享模板
+//
+// @Summary 保存分享模板
+// @Description 新建或覆盖同名分享模板，创建分享时请求中未设置的选项取模板的值。每个用户最多保存20个模板
+// @Tags 分享
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "模板名称"
+// @Param request body SaveShareTemplateRequest true "模板设置"
+// @Success 200 {object} utils.Response{data=share.ShareTemplate} "保存的模板"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "模板数量已达上限"
+// @Router /api/v1/shares/templates/{name} [put]
+func (h *ShareTemplateHandler) SaveTemplate(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	var req SaveShareTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+		return
+	}
+
+	template, err := h.service.Save(c.Request.Context(), userID, &sharesvc.ShareTemplate{
+		Name:             c.Param("name"),
+		Permission:       req.Permission,
+		PasswordRequired: req.PasswordRequired,
+		ExpireDays:       req.ExpireDays,
+		MaxAccess:        req.MaxAccess,
+		MaxDownload:      req.MaxDownload,
+		IsDefault:        req.IsDefault,
+	})
+	if err != nil {
+		respondServiceError(c, err, "保存分享模板失败")
+		return
+	}
+
+	utils.Success(c, template)
+}
+
+// DeleteTemplate 删除分享模板
+//
+// @Summary 删除分享模板
+// @Description 删除分享模板，删除的是默认模板时同时取消默认模板。已创建的分享不受影响
+// @Tags 分享
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "模板名称"
+// @Success 200 {object} utils.Response "删除成功"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 404 {object} utils.Response "分享模板不存在"
+// @Router /api/v1/shares/templates/{name} [delete]
+func (h *ShareTemplateHandler) DeleteTemplate(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	if err := h.service.Delete(c.Request.Context(), userID, c.Param("name")); err != nil {
+		respondServiceError(c, err, "删除分享模板失败")
+		return
+	}
+
+	utils.Success(c, nil)
+}
+
+// SetDefaultTemplate 设为默认分享模板
+//
+// @Summary 设为默认分享模板
+// @Description 创建分享时未指定模板且未设置no_template时自动应用默认模板
+// @Tags 分享
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "模板名称"
+// @Success 200 {object} utils.Response "设置成功"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 404 {object} utils.Response "分享模板不存在"
+// @Router /api/v1/shares/templates/{name}/default [put]
+func (h *ShareTemplateHandler) SetDefaultTemplate(c *gin.Context) {
+	h.setDefault(c, true)
+}
+
+// UnsetDefaultTemplate 取消默认分享模板
+//
+// @Summary 取消默认分享模板
+// @Description 取消后创建分享不再自动应用模板；该模板不是默认模板时不做修改
+// @Tags 分享
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "模板名称"
+// @Success 200 {object} utils.Response "取消成功"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 404 {object} utils.Response "分享模板不存在"
+// @Router /api/v1/shares/templates/{name}/default [delete]
+func (h *ShareTemplateHandler) UnsetDefaultTemplate(c *gin.Context) {
+	h.setDefault(c, false)
+}
+
+// setDefault 设置或取消默认模板
+func (h *ShareTemplateHandler) setDefault(c *gin.Context, isDefault bool) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	if err := h.service.SetDefault(c.Request.Context(), userID, c.Param("name"), isDefault); err != nil {
+		respondServiceError(c, err, "设置默认分享模板失败")
+		return
+	}
+
+	utils.Success(c, nil)
+}
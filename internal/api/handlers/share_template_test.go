@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+	sharesvc "cloudpan/internal/service/share"
+)
+
+// MockTemplateService 模拟分享模板服务
+type MockTemplateService struct {
+	mock.Mock
+}
+
+func (m *MockTemplateService) List(ctx context.Context, userID uint) ([]*sharesvc.ShareTemplate, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*sharesvc.ShareTemplate), args.Error(1)
+}
+
+func (m *MockTemplateService) Save(ctx context.Context, userID uint, template *sharesvc.ShareTemplate) (*sharesvc.ShareTemplate, error) {
+	args := m.Called(ctx, userID, template)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*sharesvc.ShareTemplate), args.Error(1)
+}
+
+func (m *MockTemplateService) Delete(ctx context.Context, userID uint, name string) error {
+	args := m.Called(ctx, userID, name)
+	return args.Error(0)
+}
+
+func (m *MockTemplateService) SetDefault(ctx context.Context, userID uint, name string, isDefault bool) error {
+	args := m.Called(ctx, userID, name, isDefault)
+	return args.Error(0)
+}
+
+func (m *MockTemplateService) Apply(ctx context.Context, userID uint, req *sharesvc.CreateShareRequest) error {
+	args := m.Called(ctx, userID, req)
+	return args.Error(0)
+}
+
+func setupShareTemplateRouter(service *MockTemplateService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler := NewShareTemplateHandler(service, zap.NewNop())
+	authed := router.Group("", func(c *gin.Context) {
+		c.Set("user_id", uint64(7))
+	})
+	authed.GET("/shares/templates", handler.ListTemplates)
+	authed.PUT("/shares/templates/:name", handler.SaveTemplate)
+	authed.DELETE("/shares/templates/:name", handler.DeleteTemplate)
+	authed.PUT("/shares/templates/:name/default", handler.SetDefaultTemplate)
+	authed.DELETE("/shares/templates/:name/default", handler.UnsetDefaultTemplate)
+	return router
+}
+
+func TestShareTemplateHandler_ListTemplates(t *testing.T) {
+	service := new(MockTemplateService)
+	service.On("List", mock.Anything, uint(7)).Return([]*sharesvc.ShareTemplate{{Name: "客户", ExpireDays: 7, IsDefault: true}}, nil)
+
+	w := httptest.NewRecorder()
+	setupShareTemplateRouter(service).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/shares/templates", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"is_default":true`)
+}
+
+func TestShareTemplateHandler_SaveTemplate(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		service := new(MockTemplateService)
+		service.On("Save", mock.Anything, uint(7), mock.MatchedBy(func(template *sharesvc.ShareTemplate) bool {
+			return template.Name == "client" && template.PasswordRequired && template.ExpireDays == 7 && template.IsDefault
+		})).Return(&sharesvc.ShareTemplate{Name: "client", PasswordRequired: true, ExpireDays: 7, IsDefault: true}, nil)
+
+		body := `{"password_required":true,"expire_days":7,"is_default":true}`
+		w := httptest.NewRecorder()
+		setupShareTemplateRouter(service).ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/shares/templates/client", strings.NewReader(body)))
+
+		require.Equal(t, http.StatusOK, w.Code)
+		service.AssertExpectations(t)
+	})
+
+	t.Run("limit reached", func(t *testing.T) {
+		service := new(MockTemplateService)
+		service.On("Save", mock.Anything, uint(7), mock.Anything).
+			Return(nil, pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "最多保存20个分享模板"))
+
+		w := httptest.NewRecorder()
+		setupShareTemplateRouter(service).ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/shares/templates/client", strings.NewReader(`{}`)))
+
+		assert.Equal(t, utils.CodeForbidden, decodeShareResponse(t, w).Code)
+	})
+}
+
+func TestShareTemplateHandler_DefaultAndDelete(t *testing.T) {
+	service := new(MockTemplateService)
+	service.On("SetDefault", mock.Anything, uint(7), "client", true).Return(nil).Once()
+	service.On("SetDefault", mock.Anything, uint(7), "client", false).Return(nil).Once()
+	service.On("Delete", mock.Anything, uint(7), "missing").
+		Return(pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "分享模板不存在"))
+	router := setupShareTemplateRouter(service)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/shares/templates/client/default", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/shares/templates/client/default", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/shares/templates/missing", nil))
+	assert.Equal(t, utils.CodeNotFound, decodeShareResponse(t, w).Code)
+	service.AssertExpectations(t)
+}
+
+func TestShareCreationHandler_AppliesTemplate(t *testing.T) {
+	newRouter := func(service *MockCreationService, templates *MockTemplateService) *gin.Engine {
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		handler := NewShareCreationHandler(service, zap.NewNop())
+		handler.SetTemplateService(templates)
+		router.POST("/shares", func(c *gin.Context) {
+			c.Set("user_id", uint64(7))
+		}, handler.CreateShare)
+		return router
+	}
+
+	t.Run("applied before create", func(t *testing.T) {
+		service := new(MockCreationService)
+		templates := new(MockTemplateService)
+		templates.On("Apply", mock.Anything, uint(7), mock.Anything).Run(func(args mock.Arguments) {
+			args.Get(2).(*sharesvc.CreateShareRequest).ExpireDays = 7
+		}).Return(nil)
+		service.On("Create", mock.Anything, uint(7), mock.MatchedBy(func(req *sharesvc.CreateShareRequest) bool {
+			return req.FileID == 5 && req.ExpireDays == 7
+		})).Return(&models.FileShare{FileID: 5, ShareCode: "abc"}, nil)
+
+		w := httptest.NewRecorder()
+		newRouter(service, templates).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/shares", strings.NewReader(`{"file_id":5}`)))
+
+		require.Equal(t, http.StatusOK, w.Code)
+		service.AssertExpectations(t)
+	})
+
+	t.Run("password required", func(t *testing.T) {
+		service := new(MockCreationService)
+		templates := new(MockTemplateService)
+		templates.On("Apply", mock.Anything, uint(7), mock.Anything).
+			Return(pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "分享模板「客户」要求设置分享密码"))
+
+		w := httptest.NewRecorder()
+		newRouter(service, templates).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/shares", strings.NewReader(`{"file_id":5}`)))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		service.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/utils"
+	sharesvc "cloudpan/internal/service/share"
+)
+
+// MockShareService 模拟分享服务
+type MockShareService struct {
+	mock.Mock
+}
+
+func (m *MockShareService) SetPassword(ctx context.Context, userID, shareID uint, password string) error {
+	return m.Called(ctx, userID, shareID, password).Error(0)
+}
+
+func (m *MockShareService) VerifyPassword(ctx context.Context, code, password, clientIP string) (*sharesvc.ShareAccess, error) {
+	args := m.Called(ctx, code, password, clientIP)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*sharesvc.ShareAccess), args.Error(1)
+}
+
+func (m *MockShareService) Revoke(ctx context.Context, userID, shareID uint) error {
+	return m.Called(ctx, userID, shareID).Error(0)
+}
+
+func (m *MockShareService) GetTransferStatus(ctx context.Context, code string) (*sharesvc.TransferStatus, error) {
+	args := m.Called(ctx, code)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*sharesvc.TransferStatus), args.Error(1)
+}
+
+func (m *MockShareService) CheckTransfer(ctx context.Context, code string) (*sharesvc.TransferStatus, error) {
+	args := m.Called(ctx, code)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*sharesvc.TransferStatus), args.Error(1)
+}
+
+func (m *MockShareService) RecordTransfer(ctx context.Context, shareID uint, bytes int64) error {
+	return m.Called(ctx, shareID, bytes).Error(0)
+}
+
+func setupShareRouter(service *MockShareService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler := NewShareHandler(service, zap.NewNop())
+	router.POST("/public/shares/:code/verify", handler.VerifyPassword)
+	router.GET("/public/shares/:code/transfer", handler.GetTransferStatus)
+	router.PUT("/shares/:id/password", func(c *gin.Context) {
+		c.Set("user_id", uint64(7))
+		handler.SetPassword(c)
+	})
+	router.DELETE("/shares/:id", func(c *gin.Context) {
+		c.Set("user_id", uint64(7))
+		handler.RevokeShare(c)
+	})
+	return router
+}
+
+func decodeShareResponse(t *testing.T, w *httptest.ResponseRecorder) utils.Response {
+	var resp utils.Response
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return resp
+}
+
+func TestShareHandler_VerifyPassword(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		service := new(MockShareService)
+		service.On("VerifyPassword", mock.Anything, "abc123", "secret", "192.0.2.1").
+			Return(&sharesvc.ShareAccess{ShareID: 3, ShareCode: "abc123", FileID: 10}, nil)
+
+		req := httptest.NewRequest(http.MethodPost, "/public/shares/abc123/verify", strings.NewReader(`{"password":"secret"}`))
+		req.RemoteAddr = "192.0.2.1:1234"
+		w := httptest.NewRecorder()
+		setupShareRouter(service).ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, utils.CodeSuccess, decodeShareResponse(t, w).Code)
+		service.AssertExpectations(t)
+	})
+
+	t.Run("locked", func(t *testing.T) {
+		service := new(MockShareService)
+		service.On("VerifyPassword", mock.Anything, "abc123", "wrong", mock.Anything).
+			Return(nil, pkgErrors.WrapError(pkgErrors.ErrTooManyRequests, "分享密码错误次数过多，已临时锁定，请15分钟后再试"))
+
+		w := httptest.NewRecorder()
+		setupShareRouter(service).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/public/shares/abc123/verify", strings.NewReader(`{"password":"wrong"}`)))
+
+		assert.Equal(t, utils.CodeTooManyRequests, decodeShareResponse(t, w).Code)
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		service := new(MockShareService)
+		service.On("VerifyPassword", mock.Anything, "abc123", "wrong", mock.Anything).
+			Return(nil, pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, "分享密码错误"))
+
+		w := httptest.NewRecorder()
+		setupShareRouter(service).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/public/shares/abc123/verify", strings.NewReader(`{"password":"wrong"}`)))
+
+		assert.Equal(t, utils.CodeForbidden, decodeShareResponse(t, w).Code)
+	})
+}
+
+func TestShareHandler_SetPassword(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		service := new(MockShareService)
+		service.On("SetPassword", mock.Anything, uint(7), uint(3), "new-secret").Return(nil)
+
+		w := httptest.NewRecorder()
+		setupShareRouter(service).ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/shares/3/password", strings.NewReader(`{"password":"new-secret"}`)))
+
+		require.Equal(t, http.StatusOK, w.Code)
+		service.AssertExpectations(t)
+	})
+
+	t.Run("invalid id", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		setupShareRouter(new(MockShareService)).ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/shares/abc/password", strings.NewReader(`{}`)))
+
+		assert.Equal(t, utils.CodeBadRequest, decodeShareResponse(t, w).Code)
+	})
+}
+
+func TestShareHandler_RevokeShare(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		service := new(MockShareService)
+		service.On("Revoke", mock.Anything, uint(7), uint(3)).Return(nil)
+
+		w := httptest.NewRecorder()
+		setupShareRouter(service).ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/shares/3", nil))
+
+		require.Equal(t, http.StatusOK, w.Code)
+		service.AssertExpectations(t)
+	})
+
+	t.Run("not owner", func(t *testing.T) {
+		service := new(MockShareService)
+		service.On("Revoke", mock.Anything, uint(7), uint(3)).
+			Return(pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, "只有分享者可以撤销分享"))
+
+		w := httptest.NewRecorder()
+		setupShareRouter(service).ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/shares/3", nil))
+
+		assert.Equal(t, utils.CodeForbidden, decodeShareResponse(t, w).Code)
+	})
+}
+
+func TestShareHandler_GetTransferStatus(t *testing.T) {
+	service := new(MockShareService)
+	service.On("GetTransferStatus", mock.Anything, "abc123").
+		Return(&sharesvc.TransferStatus{ShareID: 3, Limit: 100, Used: 100, Exhausted: true}, nil)
+
+	w := httptest.NewRecorder()
+	setupShareRouter(service).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/public/shares/abc123/transfer", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	var resp struct {
+		Data sharesvc.TransferStatus `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.True(t, resp.Data.Exhausted)
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/storage"
+	"cloudpan/internal/pkg/utils"
+)
+
+// StorageHealthHandler 存储后端健康检查处理器
+type StorageHealthHandler struct {
+	monitor *storage.HealthMonitor
+	logger  *zap.Logger
+}
+
+// NewStorageHealthHandler 创建存储后端健康检查处理器
+func NewStorageHealthHandler(monitor *storage.HealthMonitor, logger *zap.Logger) *StorageHealthHandler {
+	return &StorageHealthHandler{
+		monitor: monitor,
+		logger:  logger,
+	}
+}
+
+// StorageHealthResponse 存储后端健康状况
+type StorageHealthResponse struct {
+	Healthy  bool                    `json:"healthy"`  // 全部后端未熔断
+	Readable bool                    `json:"readable"` // 至少一个后端可以提供读取
+	Backends []storage.BackendHealth `json:"backends"` // 各后端状态，主存储在前
+}
+
+// GetStorageHealth 查询存储后端健康状况
+//
+// @Summary 查询存储后端健康状况
+// @Description 返回本实例主存储和只读副本的熔断状态(closed/open/half_open)、连续失败次数、最近一次探测的时间和耗时、最近一次错误，
+// @Description 以及累计请求数、失败数、熔断期间拒绝的请求数和主存储不可用时转到副本的读取数
+// @Tags 系统
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response{data=StorageHealthResponse} "存储后端健康状况"
+// @Failure 403 {object} utils.Response "需要管理员权限"
+// @Router /api/v1/system/storage [get]
+func (h *StorageHealthHandler) GetStorageHealth(c *gin.Context) {
+	response := StorageHealthResponse{
+		Healthy:  true,
+		Readable: h.monitor.Readable(),
+		Backends: h.monitor.Status(),
+	}
+	for _, backend := range response.Backends {
+		if backend.State != storage.CircuitClosed {
+			response.Healthy = false
+			break
+		}
+	}
+	utils.Success(c, response)
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/storage"
+)
+
+func TestStorageHealthHandler_GetStorageHealth(t *testing.T) {
+	primary, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	replica, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	monitor := storage.NewHealthMonitor(primary, replica, storage.HealthOptions{}, nil)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/system/storage", NewStorageHealthHandler(monitor, zap.NewNop()).GetStorageHealth)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/system/storage", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	data := decodeShareResponse(t, w).Data.(map[string]interface{})
+	assert.Equal(t, true, data["healthy"])
+	assert.Equal(t, true, data["readable"])
+	backends := data["backends"].([]interface{})
+	require.Len(t, backends, 2)
+	first := backends[0].(map[string]interface{})
+	assert.Equal(t, storage.RolePrimary, first["role"])
+	assert.Equal(t, storage.CircuitClosed, first["state"])
+	assert.Equal(t, storage.RoleReplica, backends[1].(map[string]interface{})["role"])
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/service/user"
+)
+
+// TrashUsageReader 统计用户回收站用量，由 filerepo.TrashRepository 实现
+type TrashUsageReader interface {
+	UsageByUser(ctx context.Context, userID uint) (items int64, size int64, err error)
+}
+
+// TrashQuotaUsage 回收站用量
+type TrashQuotaUsage struct {
+	Items int64 `json:"items"` // 回收站中的项目数
+	Size  int64 `json:"size"`  // 回收站占用的空间，已计入used，清空回收站后释放
+}
+
+// StorageQuotaResponse 存储配额查询结果
+type StorageQuotaResponse struct {
+	*user.StorageUsage
+	Trash *TrashQuotaUsage `json:"trash,omitempty"` // 回收站用量，统计失败时省略
+}
+
+// StorageQuotaHandler 存储配额查询处理器
+type StorageQuotaHandler struct {
+	service user.StorageQuotaService
+	trash   TrashUsageReader
+	logger  *zap.Logger
+}
+
+// NewStorageQuotaHandler 创建存储配额查询处理器
+func NewStorageQuotaHandler(service user.StorageQuotaService, logger *zap.Logger) *StorageQuotaHandler {
+	return &StorageQuotaHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// SetTrashUsage 设置回收站用量统计，未设置时配额查询结果不包含回收站用量
+func (h *StorageQuotaHandler) SetTrashUsage(reader TrashUsageReader) {
+	h.trash = reader
+}
+
+// GetMyQuota 查询当前用户的存储配额
+//
+// @Summary 查询存储配额
+// @Description 返回存储配额、已使用空间、进行中的上传预留的空间、剩余可用空间和回收站用量。申请上传时按文件大小预留空间，完成后计入已使用，失败或过期后释放。quota为0表示不限额，此时available为-1
+// @Tags 用户
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response{data=StorageQuotaResponse} "获取成功"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 404 {object} utils.Response "用户不存在"
+// @Failure 500 {object} utils.Response "内部服务器错误"
+// @Router /api/v1/users/me/quota [get]
+func (h *StorageQuotaHandler) GetMyQuota(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	usage, err := h.service.GetUsage(c.Request.Context(), userID)
+	if err != nil {
+		h.logger.Warn("Failed to get storage quota", zap.Uint("user_id", userID), zap.Error(err))
+		respondServiceError(c, err, "查询存储配额失败")
+		return
+	}
+
+	resp := &StorageQuotaResponse{StorageUsage: usage}
+	if h.trash != nil {
+		items, size, err := h.trash.UsageByUser(c.Request.Context(), userID)
+		if err != nil {
+			// 回收站用量只是附加信息，统计失败不影响配额查询
+			h.logger.Warn("Failed to get trash usage", zap.Uint("user_id", userID), zap.Error(err))
+		} else {
+			resp.Trash = &TrashQuotaUsage{Items: items, Size: size}
+		}
+	}
+	utils.Success(c, resp)
+}
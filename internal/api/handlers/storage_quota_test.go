@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/service/user"
+)
+
+// stubStorageQuotaService 返回固定用量的存储配额服务
+type stubStorageQuotaService struct {
+	user.StorageQuotaService
+	usage *user.StorageUsage
+}
+
+func (s *stubStorageQuotaService) GetUsage(_ context.Context, _ uint) (*user.StorageUsage, error) {
+	if s.usage == nil {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "用户不存在")
+	}
+	return s.usage, nil
+}
+
+func TestStorageQuotaHandler_GetMyQuota(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	serve := func(service *stubStorageQuotaService, authed bool) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/users/me/quota", nil)
+		if authed {
+			c.Set("user_id", uint64(7))
+		}
+		NewStorageQuotaHandler(service, zap.NewNop()).GetMyQuota(c)
+		return w
+	}
+
+	service := &stubStorageQuotaService{usage: &user.StorageUsage{Quota: 1000, Used: 600, Reserved: 100, Available: 300, UsagePercent: 70}}
+	w := serve(service, true)
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Data user.StorageUsage `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, *service.usage, resp.Data)
+
+	assert.Equal(t, http.StatusNotFound, serve(&stubStorageQuotaService{}, true).Code)
+	assert.Equal(t, http.StatusUnauthorized, serve(service, false).Code)
+}
+
+// stubTrashUsage 返回固定回收站用量
+type stubTrashUsage struct {
+	items, size int64
+	err         error
+}
+
+func (s *stubTrashUsage) UsageByUser(_ context.Context, _ uint) (int64, int64, error) {
+	return s.items, s.size, s.err
+}
+
+func TestStorageQuotaHandler_TrashUsage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	service := &stubStorageQuotaService{usage: &user.StorageUsage{Quota: 1000, Used: 600, Available: 400, UsagePercent: 60}}
+	serve := func(trash *stubTrashUsage) *StorageQuotaResponse {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/users/me/quota", nil)
+		c.Set("user_id", uint64(7))
+		handler := NewStorageQuotaHandler(service, zap.NewNop())
+		handler.SetTrashUsage(trash)
+		handler.GetMyQuota(c)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp struct {
+			Data StorageQuotaResponse `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return &resp.Data
+	}
+
+	resp := serve(&stubTrashUsage{items: 3, size: 250})
+	assert.Equal(t, int64(600), resp.Used)
+	assert.Equal(t, &TrashQuotaUsage{Items: 3, Size: 250}, resp.Trash)
+
+	// 统计失败时仍返回配额
+	resp = serve(&stubTrashUsage{err: assert.AnError})
+	assert.Equal(t, int64(1000), resp.Quota)
+	assert.Nil(t, resp.Trash)
+}
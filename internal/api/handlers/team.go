@@ -0,0 +1,561 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/service/audit"
+	teamsvc "cloudpan/internal/service/team"
+)
+
+// 团队列表分页参数
+const (
+	defaultTeamPageSize = 20
+	maxTeamPageSize     = 100
+)
+
+// TeamHandler 团队处理器
+type TeamHandler struct {
+	securityAudit
+	service teamsvc.TeamService
+	logger  *zap.Logger
+}
+
+// NewTeamHandler 创建团队处理器
+func NewTeamHandler(service teamsvc.TeamService, logger *zap.Logger) *TeamHandler {
+	return &TeamHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// ListTeams 列出当前用户加入的团队
+//
+// @Summary 团队列表
+// @Description 分页列出当前用户加入的团队，按加入顺序排列，role为当前用户在团队中的角色。已删除但未超过恢复期限的团队status为archived，purge_at为恢复期限
+// @Tags 团队
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "页码" default(1)
+// @Param page_size query int false "每页数量(最大100)" default(20)
+// @Success 200 {object} utils.ListResponse{data=[]teamsvc.TeamInfo} "团队列表"
+// @Failure 401 {object} utils.Response "未认证"
+// @Router /api/v1/teams [get]
+func (h *TeamHandler) ListTeams(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	page, pageSize := parseTeamPage(c)
+	teams, total, err := h.service.ListTeams(c.Request.Context(), userID, page, pageSize)
+	if err != nil {
+		respondServiceError(c, err, "获取团队列表失败")
+		return
+	}
+
+	utils.SuccessList(c, teams, utils.NewPagination(page, pageSize, total))
+}
+
+// CreateTeam 创建团队
+//
+// @Summary 创建团队
+// @Description 创建团队，当前用户成为团队所有者。团队名称2-50个字符并经过敏感词过滤
+// @Tags 团队
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body teamsvc.CreateTeamRequest true "团队信息"
+// @Success 200 {object} utils.Response{data=teamsvc.TeamInfo} "创建的团队"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Router /api/v1/teams [post]
+func (h *TeamHandler) CreateTeam(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	var req teamsvc.CreateTeamRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+		return
+	}
+
+	team, err := h.service.CreateTeam(c.Request.Context(), userID, &req)
+	if err != nil {
+		respondServiceError(c, err, "创建团队失败")
+		return
+	}
+
+	utils.Success(c, team)
+}
+
+// GetTeam 获取团队信息
+//
+// @Summary 团队详情
+// @Description 获取团队信息，只有团队成员可以查看，非成员返回团队不存在
+// @Tags 团队
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "团队ID"
+// @Success 200 {object} utils.Response{data=teamsvc.TeamInfo} "团队信息"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 404 {object} utils.Response "团队不存在"
+// @Router /api/v1/teams/{id} [get]
+func (h *TeamHandler) GetTeam(c *gin.Context) {
+	userID, teamID, ok := h.parseTarget(c)
+	if !ok {
+		return
+	}
+
+	team, err := h.service.GetTeam(c.Request.Context(), userID, teamID)
+	if err != nil {
+		respondServiceError(c, err, "获取团队信息失败")
+		return
+	}
+
+	utils.Success(c, team)
+}
+
+// UpdateTeam 更新团队信息
+//
+// @Summary 更新团队
+// @Description 只更新请求中出现的字段，只有所有者和管理员可以修改。max_members不能少于当前成员数
+// @Tags 团队
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "团队ID"
+// @Param request body teamsvc.UpdateTeamRequest true "更新内容"
+// @Success 200 {object} utils.Response{data=teamsvc.TeamInfo} "更新后的团队"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "无权修改团队"
+// @Failure 404 {object} utils.Response "团队不存在"
+// @Router /api/v1/teams/{id} [patch]
+func (h *TeamHandler) UpdateTeam(c *gin.Context) {
+	userID, teamID, ok := h.parseTarget(c)
+	if !ok {
+		return
+	}
+
+	var req teamsvc.UpdateTeamRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+		return
+	}
+
+	team, err := h.service.UpdateTeam(c.Request.Context(), userID, teamID, &req)
+	if err != nil {
+		respondServiceError(c, err, "更新团队失败")
+		return
+	}
+
+	utils.Success(c, team)
+}
+
+// DeleteTeam 删除团队
+//
+// @Summary 删除团队
+// @Description 删除团队，只有所有者可以删除。团队和成员先归档：恢复期限(默认30天)内成员只能查看团队、成员和团队文件，
+// @Description 团队授予的文件权限只保留查看，所有者可以恢复；过期后彻底删除，共享到团队的文件只解除共享，文件本身不受影响
+// @Tags 团队
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "团队ID"
+// @Success 200 {object} utils.Response "删除成功"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "无权删除团队或团队已删除"
+// @Failure 404 {object} utils.Response "团队不存在"
+// @Router /api/v1/teams/{id} [delete]
+func (h *TeamHandler) DeleteTeam(c *gin.Context) {
+	userID, teamID, ok := h.parseTarget(c)
+	if !ok {
+		return
+	}
+
+	before := h.teamSnapshot(c, userID, teamID)
+	if err := h.service.DeleteTeam(c.Request.Context(), userID, teamID); err != nil {
+		respondServiceError(c, err, "删除团队失败")
+		return
+	}
+	h.recordSecurity(c, h.logger, &audit.Event{
+		UserID:       userID,
+		Action:       audit.SecurityActionTeamDelete,
+		ResourceType: audit.ResourceTeam,
+		ResourceID:   strconv.FormatUint(uint64(teamID), 10),
+		Before:       before,
+	})
+
+	utils.SuccessWithMessage(c, "团队已删除，恢复期限内可由所有者恢复", nil)
+}
+
+// RestoreTeam 恢复已删除的团队
+//
+// @Summary 恢复团队
+// @Description 在恢复期限内恢复已删除的团队，只有所有者可以恢复。归档的成员恢复为活跃，团队文件和团队授予的文件权限恢复原有权限
+// @Tags 团队
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "团队ID"
+// @Success 200 {object} utils.Response{data=teamsvc.TeamInfo} "恢复后的团队"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "无权恢复、团队未删除或已超过恢复期限"
+// @Failure 404 {object} utils.Response "团队不存在"
+// @Router /api/v1/teams/{id}/restore [post]
+func (h *TeamHandler) RestoreTeam(c *gin.Context) {
+	userID, teamID, ok := h.parseTarget(c)
+	if !ok {
+		return
+	}
+
+	team, err := h.service.RestoreTeam(c.Request.Context(), userID, teamID)
+	if err != nil {
+		respondServiceError(c, err, "恢复团队失败")
+		return
+	}
+	h.recordSecurity(c, h.logger, &audit.Event{
+		UserID:       userID,
+		Action:       audit.SecurityActionTeamRestore,
+		ResourceType: audit.ResourceTeam,
+		ResourceID:   strconv.FormatUint(uint64(teamID), 10),
+		ResourceName: team.Name,
+		After:        map[string]interface{}{"status": team.Status, "member_count": team.MemberCount},
+	})
+
+	utils.Success(c, team)
+}
+
+// ListMembers 列出团队成员
+//
+// @Summary 团队成员列表
+// @Description 列出团队成员，按加入顺序排列，团队的任何成员都可以查看
+// @Tags 团队
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "团队ID"
+// @Success 200 {object} utils.Response{data=[]teamsvc.MemberInfo} "成员列表"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 404 {object} utils.Response "团队不存在"
+// @Router /api/v1/teams/{id}/members [get]
+func (h *TeamHandler) ListMembers(c *gin.Context) {
+	userID, teamID, ok := h.parseTarget(c)
+	if !ok {
+		return
+	}
+
+	members, err := h.service.ListMembers(c.Request.Context(), userID, teamID)
+	if err != nil {
+		respondServiceError(c, err, "获取团队成员失败")
+		return
+	}
+
+	utils.Success(c, members)
+}
+
+// InviteMember 邀请成员
+//
+// @Summary 邀请团队成员
+// @Description 按邮箱或用户名邀请已注册用户，被邀请的用户直接以指定角色(admin/member/viewer，默认member)加入团队。
+// @Description 所有者和管理员可以邀请成员，只有所有者可以邀请管理员
+// @Tags 团队
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "团队ID"
+// @Param request body teamsvc.InviteMemberRequest true "被邀请用户和角色"
+// @Success 200 {object} utils.Response{data=teamsvc.MemberInfo} "新成员"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "无权邀请成员、团队已停用或成员已达上限"
+// @Failure 404 {object} utils.Response "团队或用户不存在"
+// @Failure 409 {object} utils.Response "用户已是团队成员"
+// @Router /api/v1/teams/{id}/members [post]
+func (h *TeamHandler) InviteMember(c *gin.Context) {
+	userID, teamID, ok := h.parseTarget(c)
+	if !ok {
+		return
+	}
+
+	var req teamsvc.InviteMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+		return
+	}
+
+	member, err := h.service.InviteMember(c.Request.Context(), userID, teamID, &req)
+	if err != nil {
+		respondServiceError(c, err, "邀请成员失败")
+		return
+	}
+
+	utils.Success(c, member)
+}
+
+// UpdateMemberRole 修改成员角色
+//
+// @Summary 修改成员角色
+// @Description 修改成员角色为admin、member或viewer，只有所有者可以任命或撤销管理员。
+// @Description 角色为owner时表示转让团队所有权，只有所有者可以转让，原所有者成为管理员
+// @Tags 团队
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "团队ID"
+// @Param user_id path int true "成员用户ID"
+// @Param request body teamsvc.UpdateMemberRoleRequest true "新角色"
+// @Success 200 {object} utils.Response{data=teamsvc.MemberInfo} "修改后的成员"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "无权修改成员角色"
+// @Failure 404 {object} utils.Response "团队或成员不存在"
+// @Router /api/v1/teams/{id}/members/{user_id}/role [put]
+func (h *TeamHandler) UpdateMemberRole(c *gin.Context) {
+	userID, teamID, ok := h.parseTarget(c)
+	if !ok {
+		return
+	}
+	memberID, ok := parseIDParam(c, "user_id")
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "成员ID格式错误")
+		return
+	}
+
+	var req teamsvc.UpdateMemberRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+		return
+	}
+
+	before := h.memberSnapshot(c, userID, teamID, memberID)
+	member, err := h.service.UpdateMemberRole(c.Request.Context(), userID, teamID, memberID, req.Role)
+	if err != nil {
+		respondServiceError(c, err, "修改成员角色失败")
+		return
+	}
+	h.recordSecurity(c, h.logger, &audit.Event{
+		UserID:       userID,
+		Action:       audit.SecurityActionTeamRoleChange,
+		ResourceType: audit.ResourceTeam,
+		ResourceID:   strconv.FormatUint(uint64(teamID), 10),
+		Before:       before,
+		After:        map[string]interface{}{"user_id": member.UserID, "role": member.Role, "status": member.Status},
+	})
+
+	utils.Success(c, member)
+}
+
+// RemoveMember 移除成员
+//
+// @Summary 移除团队成员
+// @Description 将成员移出团队，user_id为当前用户时表示离开团队。管理员只能移除普通成员和只读成员，所有者不能离开团队
+// @Tags 团队
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "团队ID"
+// @Param user_id path int true "成员用户ID"
+// @Success 200 {object} utils.Response "移除成功"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "无权移除成员"
+// @Failure 404 {object} utils.Response "团队或成员不存在"
+// @Router /api/v1/teams/{id}/members/{user_id} [delete]
+func (h *TeamHandler) RemoveMember(c *gin.Context) {
+	userID, teamID, ok := h.parseTarget(c)
+	if !ok {
+		return
+	}
+	memberID, ok := parseIDParam(c, "user_id")
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "成员ID格式错误")
+		return
+	}
+
+	before := h.memberSnapshot(c, userID, teamID, memberID)
+	if err := h.service.RemoveMember(c.Request.Context(), userID, teamID, memberID); err != nil {
+		respondServiceError(c, err, "移除成员失败")
+		return
+	}
+	h.recordSecurity(c, h.logger, &audit.Event{
+		UserID:       userID,
+		Action:       audit.SecurityActionTeamMemberRemove,
+		ResourceType: audit.ResourceTeam,
+		ResourceID:   strconv.FormatUint(uint64(teamID), 10),
+		Before:       before,
+	})
+
+	utils.SuccessWithMessage(c, "成员已移除", nil)
+}
+
+// ListFiles 列出团队文件
+//
+// @Summary 团队文件列表
+// @Description 分页列出共享到团队的文件，按共享时间倒序，已过期的共享和已删除的文件不列出
+// @Tags 团队
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "团队ID"
+// @Param page query int false "页码" default(1)
+// @Param page_size query int false "每页数量(最大100)" default(20)
+// @Success 200 {object} utils.ListResponse{data=[]teamsvc.TeamFileInfo} "团队文件"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 404 {object} utils.Response "团队不存在"
+// @Router /api/v1/teams/{id}/files [get]
+func (h *TeamHandler) ListFiles(c *gin.Context) {
+	userID, teamID, ok := h.parseTarget(c)
+	if !ok {
+		return
+	}
+
+	page, pageSize := parseTeamPage(c)
+	files, total, err := h.service.ListFiles(c.Request.Context(), userID, teamID, page, pageSize)
+	if err != nil {
+		respondServiceError(c, err, "获取团队文件失败")
+		return
+	}
+
+	utils.SuccessList(c, files, utils.NewPagination(page, pageSize, total))
+}
+
+// ShareFile 共享文件到团队
+//
+// @Summary 共享文件到团队
+// @Description 将自己的文件共享到团队，只读成员不能共享。permission为成员对文件的权限(view/download/edit/manage)，默认view
+// @Tags 团队
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "团队ID"
+// @Param request body teamsvc.ShareFileRequest true "共享的文件"
+// @Success 200 {object} utils.Response{data=teamsvc.TeamFileInfo} "团队文件"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "只读成员不能共享文件"
+// @Failure 404 {object} utils.Response "团队或文件不存在"
+// @Failure 409 {object} utils.Response "文件已共享到该团队"
+// @Router /api/v1/teams/{id}/files [post]
+func (h *TeamHandler) ShareFile(c *gin.Context) {
+	userID, teamID, ok := h.parseTarget(c)
+	if !ok {
+		return
+	}
+
+	var req teamsvc.ShareFileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+		return
+	}
+
+	file, err := h.service.ShareFile(c.Request.Context(), userID, teamID, &req)
+	if err != nil {
+		respondServiceError(c, err, "共享文件到团队失败")
+		return
+	}
+
+	utils.Success(c, file)
+}
+
+// RemoveFile 将文件移出团队
+//
+// @Summary 移除团队文件
+// @Description 将文件移出团队，文件本身不受影响。共享者本人、所有者和管理员可以移除
+// @Tags 团队
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "团队ID"
+// @Param file_id path int true "文件ID"
+// @Success 200 {object} utils.Response "移除成功"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "无权移除文件"
+// @Failure 404 {object} utils.Response "团队或文件不存在"
+// @Router /api/v1/teams/{id}/files/{file_id} [delete]
+func (h *TeamHandler) RemoveFile(c *gin.Context) {
+	userID, teamID, ok := h.parseTarget(c)
+	if !ok {
+		return
+	}
+	fileID, ok := parseIDParam(c, "file_id")
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "文件ID格式错误")
+		return
+	}
+
+	if err := h.service.RemoveFile(c.Request.Context(), userID, teamID, fileID); err != nil {
+		respondServiceError(c, err, "移除团队文件失败")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "文件已移出团队", nil)
+}
+
+// parseTarget 解析当前用户和路径中的团队ID，失败时已写入响应
+func (h *TeamHandler) parseTarget(c *gin.Context) (uint, uint, bool) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return 0, 0, false
+	}
+	teamID, ok := parseIDParam(c, "id")
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "团队ID格式错误")
+		return 0, 0, false
+	}
+	return userID, teamID, true
+}
+
+// teamSnapshot 读取团队变更前的快照用于安全审计，未设置审计服务或读取失败时返回nil
+func (h *TeamHandler) teamSnapshot(c *gin.Context, userID, teamID uint) map[string]interface{} {
+	if h.securityAuditor == nil {
+		return nil
+	}
+	team, err := h.service.GetTeam(c.Request.Context(), userID, teamID)
+	if err != nil {
+		return nil
+	}
+	return map[string]interface{}{"name": team.Name, "owner_id": team.OwnerID, "member_count": team.MemberCount, "file_count": team.FileCount}
+}
+
+// memberSnapshot 读取成员变更前的快照用于安全审计，未设置审计服务或读取失败时返回nil
+func (h *TeamHandler) memberSnapshot(c *gin.Context, userID, teamID, memberID uint) map[string]interface{} {
+	if h.securityAuditor == nil {
+		return nil
+	}
+	members, err := h.service.ListMembers(c.Request.Context(), userID, teamID)
+	if err != nil {
+		return nil
+	}
+	for _, member := range members {
+		if member.UserID == memberID {
+			return map[string]interface{}{"user_id": member.UserID, "role": member.Role, "status": member.Status}
+		}
+	}
+	return nil
+}
+
+// parseTeamPage 解析分页参数
+func parseTeamPage(c *gin.Context) (int, int) {
+	page, _ := strconv.Atoi(c.Query("page"))
+	if page < 1 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(c.Query("page_size"))
+	if pageSize < 1 {
+		pageSize = defaultTeamPageSize
+	}
+	if pageSize > maxTeamPageSize {
+		pageSize = maxTeamPageSize
+	}
+	return page, pageSize
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/utils"
+	teamsvc "cloudpan/internal/service/team"
+)
+
+// stubTeamService 记录调用参数的团队服务
+type stubTeamService struct {
+	teamsvc.TeamService
+	err      error
+	userID   uint
+	teamID   uint
+	memberID uint
+	page     int
+	pageSize int
+	invite   *teamsvc.InviteMemberRequest
+	role     string
+}
+
+func (s *stubTeamService) CreateTeam(_ context.Context, userID uint, req *teamsvc.CreateTeamRequest) (*teamsvc.TeamInfo, error) {
+	s.userID = userID
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &teamsvc.TeamInfo{ID: 1, Name: req.Name, OwnerID: userID, Role: "owner"}, nil
+}
+
+func (s *stubTeamService) ListTeams(_ context.Context, userID uint, page, pageSize int) ([]*teamsvc.TeamInfo, int64, error) {
+	s.userID, s.page, s.pageSize = userID, page, pageSize
+	return []*teamsvc.TeamInfo{{ID: 1, Name: "设计组"}}, 1, s.err
+}
+
+func (s *stubTeamService) InviteMember(_ context.Context, userID, teamID uint, req *teamsvc.InviteMemberRequest) (*teamsvc.MemberInfo, error) {
+	s.userID, s.teamID, s.invite = userID, teamID, req
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &teamsvc.MemberInfo{UserID: 8, Username: "alice", Role: req.Role}, nil
+}
+
+func (s *stubTeamService) UpdateMemberRole(_ context.Context, userID, teamID, memberID uint, role string) (*teamsvc.MemberInfo, error) {
+	s.userID, s.teamID, s.memberID, s.role = userID, teamID, memberID, role
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &teamsvc.MemberInfo{UserID: memberID, Role: role}, nil
+}
+
+func (s *stubTeamService) RemoveMember(_ context.Context, userID, teamID, memberID uint) error {
+	s.userID, s.teamID, s.memberID = userID, teamID, memberID
+	return s.err
+}
+
+func (s *stubTeamService) DeleteTeam(_ context.Context, userID, teamID uint) error {
+	s.userID, s.teamID = userID, teamID
+	return s.err
+}
+
+func (s *stubTeamService) RestoreTeam(_ context.Context, userID, teamID uint) (*teamsvc.TeamInfo, error) {
+	s.userID, s.teamID = userID, teamID
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &teamsvc.TeamInfo{ID: teamID, Name: "设计组", Status: "active"}, nil
+}
+
+func setupTeamRouter(service *stubTeamService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler := NewTeamHandler(service, zap.NewNop())
+	teams := router.Group("/teams", func(c *gin.Context) {
+		c.Set("user_id", uint64(7))
+	})
+	teams.GET("", handler.ListTeams)
+	teams.POST("", handler.CreateTeam)
+	teams.DELETE("/:id", handler.DeleteTeam)
+	teams.POST("/:id/restore", handler.RestoreTeam)
+	teams.POST("/:id/members", handler.InviteMember)
+	teams.PUT("/:id/members/:user_id/role", handler.UpdateMemberRole)
+	teams.DELETE("/:id/members/:user_id", handler.RemoveMember)
+	return router
+}
+
+func serveTeam(router *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestTeamHandler_CreateAndList(t *testing.T) {
+	service := &stubTeamService{}
+	router := setupTeamRouter(service)
+
+	w := serveTeam(router, http.MethodPost, "/teams", `{"name":"设计组"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp utils.Response
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "设计组", resp.Data.(map[string]interface{})["name"])
+	assert.Equal(t, uint(7), service.userID)
+
+	w = serveTeam(router, http.MethodPost, "/teams", `{}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = serveTeam(router, http.MethodGet, "/teams?page=2&page_size=500", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 2, service.page)
+	assert.Equal(t, maxTeamPageSize, service.pageSize)
+}
+
+func TestTeamHandler_Members(t *testing.T) {
+	service := &stubTeamService{}
+	router := setupTeamRouter(service)
+
+	w := serveTeam(router, http.MethodPost, "/teams/3/members", `{"user":"alice@example.com","role":"viewer"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, uint(3), service.teamID)
+	assert.Equal(t, &teamsvc.InviteMemberRequest{User: "alice@example.com", Role: "viewer"}, service.invite)
+
+	w = serveTeam(router, http.MethodPut, "/teams/3/members/8/role", `{"role":"admin"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, uint(8), service.memberID)
+	assert.Equal(t, "admin", service.role)
+
+	w = serveTeam(router, http.MethodDelete, "/teams/3/members/8", "")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = serveTeam(router, http.MethodDelete, "/teams/abc/members/8", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = serveTeam(router, http.MethodDelete, "/teams/3/members/abc", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestTeamHandler_Errors(t *testing.T) {
+	cases := []struct {
+		err    error
+		status int
+	}{
+		{pkgErrors.WrapError(pkgErrors.ErrResourceExists, "该用户已是团队成员"), http.StatusConflict},
+		{pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, "只有团队所有者可以任命管理员"), http.StatusForbidden},
+		{pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "团队不存在"), http.StatusNotFound},
+		{pkgErrors.WrapErrorf(pkgErrors.ErrQuotaExceeded, "团队成员已达上限 %d 人", 50), http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		router := setupTeamRouter(&stubTeamService{err: tc.err})
+		w := serveTeam(router, http.MethodPost, "/teams/3/members", `{"user":"alice"}`)
+		assert.Equal(t, tc.status, w.Code, tc.err.Error())
+	}
+}
+
+func TestTeamHandler_DeleteAndRestore(t *testing.T) {
+	service := &stubTeamService{}
+	router := setupTeamRouter(service)
+
+	w := serveTeam(router, http.MethodDelete, "/teams/3", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, uint(3), service.teamID)
+
+	w = serveTeam(router, http.MethodPost, "/teams/4/restore", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, uint(4), service.teamID)
+	var resp struct {
+		Data teamsvc.TeamInfo `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "active", resp.Data.Status)
+
+	service.err = pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "团队已超过恢复期限")
+	w = serveTeam(router, http.MethodPost, "/teams/4/restore", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/service/user"
+)
+
+// TwoFactorHandler 两步验证(TOTP)管理处理器
+type TwoFactorHandler struct {
+	service user.TwoFactorService
+	logger  *zap.Logger
+}
+
+// NewTwoFactorHandler 创建两步验证管理处理器
+func NewTwoFactorHandler(service user.TwoFactorService, logger *zap.Logger) *TwoFactorHandler {
+	return &TwoFactorHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// TwoFactorPasswordRequest 需要确认登录密码的两步验证请求
+type TwoFactorPasswordRequest struct {
+	Password string `json:"password" binding:"required"` // 当前登录密码
+}
+
+// TwoFactorCodeRequest 提交验证码的两步验证请求
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"` // 验证器App上的6位验证码
+}
+
+// TwoFactorDisableRequest 关闭两步验证请求
+type TwoFactorDisableRequest struct {
+	Password string `json:"password" binding:"required"` // 当前登录密码
+	Code     string `json:"code" binding:"required"`     // 验证码或备用码
+}
+
+// TwoFactorBackupCodes 备用码，只在生成时返回一次
+type TwoFactorBackupCodes struct {
+	BackupCodes []string `json:"backup_codes"` // 每个备用码只能使用一次
+}
+
+// GetStatus 查询两步验证状态
+//
+// @Summary 查询两步验证状态
+// @Description 返回当前用户是否已启用两步验证以及剩余备用码数量
+// @Tags 认证
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response{data=user.TwoFactorStatus} "获取成功"
+// @Failure 401 {object} utils.Response "未认证"
+// @Router /api/v1/auth/2fa [get]
+func (h *TwoFactorHandler) GetStatus(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	status, err := h.service.Status(c.Request.Context(), userID)
+	if err != nil {
+		h.logger.Error("Failed to get two-factor status", zap.Uint("user_id", userID), zap.Error(err))
+		utils.InternalErrorWithMessage(c, "查询两步验证状态失败")
+		return
+	}
+	utils.Success(c, status)
+}
+
+// Enroll 生成两步验证密钥
+//
+// @Summary 生成两步验证密钥
+// @Description 确认登录密码后生成TOTP密钥，返回密钥和otpauth地址(用于生成二维码)。需调用启用接口提交验证码后才生效，重复调用会替换未启用的密钥
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body TwoFactorPasswordRequest true "登录密码"
+// @Success 200 {object} utils.Response{data=user.TwoFactorEnrollment} "密钥已生成"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证或密码错误"
+// @Failure 403 {object} utils.Response "两步验证已启用"
+// @Router /api/v1/auth/2fa/enroll [post]
+func (h *TwoFactorHandler) Enroll(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	var req TwoFactorPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+		return
+	}
+
+	// 验证器App中显示的账户名，优先使用邮箱
+	account := c.GetString("email")
+	if account == "" {
+		account = c.GetString("username")
+	}
+	enrollment, err := h.service.BeginEnrollment(c.Request.Context(), userID, account, req.Password)
+	if err != nil {
+		h.respondError(c, err, "生成两步验证密钥失败")
+		return
+	}
+	utils.Success(c, enrollment)
+}
+
+// Enable 启用两步验证
+//
+// @Summary 启用两步验证
+// @Description 提交验证器App上的验证码确认密钥后启用两步验证，返回10个备用码。备用码只显示这一次，请妥善保存
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body TwoFactorCodeRequest true "验证码"
+// @Success 200 {object} utils.Response{data=TwoFactorBackupCodes} "已启用"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证或验证码错误"
+// @Failure 403 {object} utils.Response "未生成密钥或已启用"
+// @Router /api/v1/auth/2fa/enable [post]
+func (h *TwoFactorHandler) Enable(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+		return
+	}
+
+	codes, err := h.service.Enable(c.Request.Context(), userID, req.Code)
+	if err != nil {
+		h.respondError(c, err, "启用两步验证失败")
+		return
+	}
+	h.logger.Info("Two-factor authentication enabled",
+		zap.Uint("user_id", userID),
+		zap.String("ip", c.ClientIP()))
+	utils.SuccessWithMessage(c, "两步验证已启用", TwoFactorBackupCodes{BackupCodes: codes})
+}
+
+// Disable 关闭两步验证
+//
+// @Summary 关闭两步验证
+// @Description 确认登录密码和验证码(或备用码)后关闭两步验证，同时删除密钥和备用码
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body TwoFactorDisableRequest true "登录密码和验证码"
+// @Success 200 {object} utils.Response "已关闭"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证、密码错误或验证码错误"
+// @Failure 403 {object} utils.Response "未启用两步验证"
+// @Router /api/v1/auth/2fa/disable [post]
+func (h *TwoFactorHandler) Disable(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	var req TwoFactorDisableRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+		return
+	}
+
+	if err := h.service.Disable(c.Request.Context(), userID, req.Password, req.Code); err != nil {
+		h.respondError(c, err, "关闭两步验证失败")
+		return
+	}
+	h.logger.Warn("Two-factor authentication disabled",
+		zap.Uint("user_id", userID),
+		zap.String("ip", c.ClientIP()))
+	utils.SuccessWithMessage(c, "两步验证已关闭", nil)
+}
+
+// RegenerateBackupCodes 重新生成备用码
+//
+// @Summary 重新生成备用码
+// @Description 确认登录密码后生成10个新的备用码，之前的备用码全部作废
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body TwoFactorPasswordRequest true "登录密码"
+// @Success 200 {object} utils.Response{data=TwoFactorBackupCodes} "已生成"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "未认证或密码错误"
+// @Failure 403 {object} utils.Response "未启用两步验证"
+// @Router /api/v1/auth/2fa/backup-codes [post]
+func (h *TwoFactorHandler) RegenerateBackupCodes(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	var req TwoFactorPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+		return
+	}
+
+	codes, err := h.service.RegenerateBackupCodes(c.Request.Context(), userID, req.Password)
+	if err != nil {
+		h.respondError(c, err, "生成备用码失败")
+		return
+	}
+	utils.SuccessWithMessage(c, "备用码已重新生成", TwoFactorBackupCodes{BackupCodes: codes})
+}
+
+// respondError 密码或验证码错误返回未认证，其他错误按服务层错误处理
+func (h *TwoFactorHandler) respondError(c *gin.Context, err error, fallbackMessage string) {
+	switch {
+	case errors.Is(err, user.ErrTwoFactorPasswordIncorrect):
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "当前密码错误")
+	case errors.Is(err, user.ErrInvalidTwoFactorCode):
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "验证码错误")
+	default:
+		respondServiceError(c, err, fallbackMessage)
+	}
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/service/user"
+)
+
+// stubTwoFactorService 只接受指定密码和验证码的两步验证服务
+type stubTwoFactorService struct {
+	enabled  bool
+	password string
+	code     string
+	account  string
+	verified int
+}
+
+func (s *stubTwoFactorService) Status(context.Context, uint) (*user.TwoFactorStatus, error) {
+	return &user.TwoFactorStatus{Enabled: s.enabled}, nil
+}
+
+func (s *stubTwoFactorService) IsEnabled(context.Context, uint) (bool, error) {
+	return s.enabled, nil
+}
+
+func (s *stubTwoFactorService) BeginEnrollment(_ context.Context, _ uint, account, password string) (*user.TwoFactorEnrollment, error) {
+	if password != s.password {
+		return nil, user.ErrTwoFactorPasswordIncorrect
+	}
+	if s.enabled {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "两步验证已启用，请先关闭")
+	}
+	s.account = account
+	return &user.TwoFactorEnrollment{Secret: "JBSWY3DPEHPK3PXP", ProvisioningURI: "otpauth://totp/CloudPan:" + account}, nil
+}
+
+func (s *stubTwoFactorService) Enable(_ context.Context, _ uint, code string) ([]string, error) {
+	if code != s.code {
+		return nil, user.ErrInvalidTwoFactorCode
+	}
+	s.enabled = true
+	return []string{"abcde-fghjk"}, nil
+}
+
+func (s *stubTwoFactorService) Disable(ctx context.Context, userID uint, password, code string) error {
+	if password != s.password {
+		return user.ErrTwoFactorPasswordIncorrect
+	}
+	if err := s.Verify(ctx, userID, code); err != nil {
+		return err
+	}
+	s.enabled = false
+	return nil
+}
+
+func (s *stubTwoFactorService) RegenerateBackupCodes(_ context.Context, _ uint, password string) ([]string, error) {
+	if password != s.password {
+		return nil, user.ErrTwoFactorPasswordIncorrect
+	}
+	return []string{"mnpqr-stuvw"}, nil
+}
+
+func (s *stubTwoFactorService) Verify(_ context.Context, _ uint, code string) error {
+	if !s.enabled {
+		return pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "未启用两步验证")
+	}
+	if code != s.code {
+		return user.ErrInvalidTwoFactorCode
+	}
+	s.verified++
+	return nil
+}
+
+func TestTwoFactorHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	service := &stubTwoFactorService{password: "testPassword123!", code: "123456"}
+	handler := NewTwoFactorHandler(service, zap.NewNop())
+
+	call := func(handle gin.HandlerFunc, body interface{}) (*httptest.ResponseRecorder, utils.Response) {
+		reqBody, _ := json.Marshal(body)
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/auth/2fa", bytes.NewBuffer(reqBody))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Set("user_id", uint64(1))
+		c.Set("email", "test@example.com")
+		handle(c)
+		return w, decodeShareResponse(t, w)
+	}
+
+	t.Run("密码错误不能生成密钥", func(t *testing.T) {
+		w, resp := call(handler.Enroll, TwoFactorPasswordRequest{Password: "wrong"})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, resp.Message, "当前密码错误")
+
+		w, _ = call(handler.Enroll, gin.H{})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("生成密钥并启用", func(t *testing.T) {
+		w, resp := call(handler.Enroll, TwoFactorPasswordRequest{Password: "testPassword123!"})
+		require.Equal(t, http.StatusOK, w.Code)
+		data, ok := resp.Data.(map[string]interface{})
+		require.True(t, ok)
+		assert.Equal(t, "JBSWY3DPEHPK3PXP", data["secret"])
+		assert.Equal(t, "test@example.com", service.account)
+
+		_, resp = call(handler.Enable, TwoFactorCodeRequest{Code: "000000"})
+		assert.Equal(t, utils.CodeUnauthorized, resp.Code)
+
+		w, resp = call(handler.Enable, TwoFactorCodeRequest{Code: "123456"})
+		require.Equal(t, http.StatusOK, w.Code)
+		data, ok = resp.Data.(map[string]interface{})
+		require.True(t, ok)
+		assert.Equal(t, []interface{}{"abcde-fghjk"}, data["backup_codes"])
+
+		// 已启用时不能重新生成密钥
+		w, _ = call(handler.Enroll, TwoFactorPasswordRequest{Password: "testPassword123!"})
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("重新生成备用码", func(t *testing.T) {
+		w, resp := call(handler.RegenerateBackupCodes, TwoFactorPasswordRequest{Password: "testPassword123!"})
+		require.Equal(t, http.StatusOK, w.Code)
+		data, ok := resp.Data.(map[string]interface{})
+		require.True(t, ok)
+		assert.Equal(t, []interface{}{"mnpqr-stuvw"}, data["backup_codes"])
+	})
+
+	t.Run("关闭需要密码和验证码", func(t *testing.T) {
+		_, resp := call(handler.Disable, TwoFactorDisableRequest{Password: "wrong", Code: "123456"})
+		assert.Equal(t, utils.CodeUnauthorized, resp.Code)
+		_, resp = call(handler.Disable, TwoFactorDisableRequest{Password: "testPassword123!", Code: "000000"})
+		assert.Equal(t, utils.CodeUnauthorized, resp.Code)
+		assert.True(t, service.enabled)
+
+		w, _ := call(handler.Disable, TwoFactorDisableRequest{Password: "testPassword123!", Code: "123456"})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.False(t, service.enabled)
+	})
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/service/file"
+)
+
+// UploadTTLHandler 分片上传有效期指标处理器
+type UploadTTLHandler struct {
+	tuner  *file.UploadTTLTuner
+	logger *zap.Logger
+}
+
+// NewUploadTTLHandler 创建分片上传有效期指标处理器
+func NewUploadTTLHandler(tuner *file.UploadTTLTuner, logger *zap.Logger) *UploadTTLHandler {
+	return &UploadTTLHandler{
+		tuner:  tuner,
+		logger: logger,
+	}
+}
+
+// ListMetrics 查询分片上传有效期指标
+//
+// @Summary 查询分片上传有效期指标
+// @Description 返回本实例按客户端类型(web/desktop/mobile/other)统计的申请、完成和过期被清理的上传数、放弃率、有效期滑动延长次数，
+// @Description 最近完成耗时的中位数和配置分位数，以及新上传当前使用的有效期。统计保存在进程内，重启后清零
+// @Tags 系统
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response{data=[]file.UploadTTLMetrics} "有效期指标"
+// @Failure 403 {object} utils.Response "需要管理员权限"
+// @Router /api/v1/admin/uploads/ttl [get]
+func (h *UploadTTLHandler) ListMetrics(c *gin.Context) {
+	utils.Success(c, h.tuner.Metrics())
+}
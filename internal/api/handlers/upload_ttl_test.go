@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/service/file"
+)
+
+func TestUploadTTLHandler_ListMetrics(t *testing.T) {
+	tuner := file.NewUploadTTLTuner(file.UploadTTLOptions{Adaptive: true, MinSamples: 1})
+	tuner.ObserveStarted(file.UploadClientMobile)
+	tuner.ObserveStarted(file.UploadClientMobile)
+	tuner.ObserveCompleted(file.UploadClientMobile, 10*time.Hour)
+	tuner.ObserveAbandoned(file.UploadClientMobile)
+	tuner.ObserveStarted(file.UploadClientDesktop)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/admin/uploads/ttl", NewUploadTTLHandler(tuner, zap.NewNop()).ListMetrics)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/uploads/ttl", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	resp := decodeShareResponse(t, w)
+	assert.Equal(t, utils.CodeSuccess, resp.Code)
+	clients := resp.Data.([]interface{})
+	require.Len(t, clients, 2)
+	desktop := clients[0].(map[string]interface{})
+	assert.Equal(t, file.UploadClientDesktop, desktop["client_type"])
+	assert.Equal(t, false, desktop["adaptive"])
+	mobile := clients[1].(map[string]interface{})
+	assert.Equal(t, file.UploadClientMobile, mobile["client_type"])
+	assert.EqualValues(t, 2, mobile["started"])
+	assert.EqualValues(t, 0.5, mobile["abandon_rate"])
+	assert.Equal(t, "20h0m0s", mobile["ttl"])
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -9,10 +10,14 @@ import (
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 
-	"cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/cache"
+	pkgErrors "cloudpan/internal/pkg/errors"
 	"cloudpan/internal/pkg/utils"
 	"cloudpan/internal/repository/models"
+	"cloudpan/internal/service/audit"
+	"cloudpan/internal/service/sso"
 	"cloudpan/internal/service/user"
+	"cloudpan/internal/service/verification"
 )
 
 // LoginRequest 登录请求结构体
@@ -56,23 +61,83 @@ type UserInfo struct {
 	CreatedAt   string `json:"created_at" example:"2024-01-01T00:00:00Z"`
 }
 
+// PendingDeletionInfo 计划删除账户的登录响应数据
+type PendingDeletionInfo struct {
+	// 账户彻底删除的计划时间，之前可通过重新激活接口恢复
+	DeletionScheduledAt string `json:"deletion_scheduled_at" example:"2024-02-01T00:00:00Z"`
+}
+
+// TwoFactorChallengeInfo 需要两步验证时的登录响应数据
+type TwoFactorChallengeInfo struct {
+	// 挑战令牌，提交验证码时使用
+	ChallengeToken string `json:"challenge_token" example:"3b5d5c3712955042212316173ccf37be"`
+	// 挑战过期时间，过期后需要重新登录
+	ExpiresAt string `json:"expires_at" example:"2024-01-01T00:05:00Z"`
+}
+
+// TwoFactorLoginRequest 两步验证登录请求结构体
+type TwoFactorLoginRequest struct {
+	// 登录响应中的挑战令牌
+	ChallengeToken string `json:"challenge_token" binding:"required" example:"3b5d5c3712955042212316173ccf37be"`
+	// 验证器App上的6位验证码或备用码；method为sms时为短信验证码
+	Code string `json:"code" binding:"required" example:"123456"`
+	// 验证方式：totp(默认，验证器App或备用码)、sms(发送到已验证手机号的短信验证码)
+	Method string `json:"method,omitempty" example:"totp"`
+}
+
 // RefreshTokenRequest 刷新令牌请求结构体
 type RefreshTokenRequest struct {
 	RefreshToken string `json:"refresh_token" binding:"required" example:"eyJhbGciOiJIUzI1NiIs..."`
 }
 
+// SessionInfo 登录会话信息
+type SessionInfo struct {
+	ID        string `json:"id" example:"9f86d081884c7d659a2feaa0c55ad015"`
+	Device    string `json:"device" example:"Mozilla/5.0 (Windows NT 10.0; Win64; x64)"`
+	IP        string `json:"ip" example:"203.0.113.7"`
+	CreatedAt string `json:"created_at" example:"2024-01-01T00:00:00Z"`
+	LastSeen  string `json:"last_seen" example:"2024-01-02T08:30:00Z"`
+	ExpiresAt string `json:"expires_at" example:"2024-01-09T08:30:00Z"`
+}
+
+// 登录会话记录参数
+const (
+	sessionIDLength        = 32  // 会话ID长度(十六进制字符)
+	maxSessionDeviceLength = 256 // 会话记录的User-Agent最大长度
+)
+
+// defaultRole 本地密码登录签发令牌使用的角色
+const defaultRole = "user"
+
+// 登录两步验证参数
+const (
+	loginChallengeIDLength    = 32              // 挑战ID长度(十六进制字符)
+	loginChallengeTTL         = 5 * time.Minute // 挑战有效期
+	loginChallengeMaxAttempts = 5               // 验证码最多错误次数，达到后需要重新登录
+)
+
 // UserLoginHandler 用户登录处理器
 type UserLoginHandler struct {
-	userService user.UserService
-	jwtManager  utils.JWTManager
-	logger      *zap.Logger
-	secretKey   string
+	securityAudit
+	userService  user.UserService
+	jwtManager   utils.JWTManager
+	tokenStore   cache.TokenStore
+	refreshStore cache.RefreshTokenStore
+	sessions     cache.SessionStore
+	twoFactor    user.TwoFactorService
+	challenges   cache.LoginChallengeStore
+	sso          sso.Service
+	ldap         sso.LDAPAuthenticator
+	inactivity   user.InactivityService
+	codes        verification.VerificationService
+	logger       *zap.Logger
+	secretKey    string
 }
 
 // NewUserLoginHandler 创建新的用户登录处理器
 func NewUserLoginHandler(userService user.UserService, logger *zap.Logger, secretKey string) (*UserLoginHandler, error) {
 	if secretKey == "" {
-		return nil, errors.NewValidationError("JWT secret key", "is required")
+		return nil, pkgErrors.NewValidationError("JWT secret key", "is required")
 	}
 
 	jwtManager, err := utils.NewDefaultJWTManager(secretKey)
@@ -88,23 +153,68 @@ func NewUserLoginHandler(userService user.UserService, logger *zap.Logger, secre
 	}, nil
 }
 
+// SetTokenStore 设置令牌吊销存储，未设置时使用全局令牌吊销存储
+func (h *UserLoginHandler) SetTokenStore(store cache.TokenStore) {
+	h.tokenStore = store
+}
+
+// SetRefreshTokenStore 设置刷新令牌登记存储，未设置时使用全局刷新令牌登记存储
+func (h *UserLoginHandler) SetRefreshTokenStore(store cache.RefreshTokenStore) {
+	h.refreshStore = store
+}
+
+// SetSessionStore 设置登录会话存储，未设置时使用全局登录会话存储
+func (h *UserLoginHandler) SetSessionStore(store cache.SessionStore) {
+	h.sessions = store
+}
+
+// SetTwoFactorService 设置两步验证服务，未设置时登录不检查两步验证
+func (h *UserLoginHandler) SetTwoFactorService(service user.TwoFactorService) {
+	h.twoFactor = service
+}
+
+// SetSSOService 设置企业单点登录服务，未设置时不提供单点登录，密码登录也不检查强制SSO
+func (h *UserLoginHandler) SetSSOService(service sso.Service) {
+	h.sso = service
+}
+
+// SetLDAPAuthenticator 设置企业目录认证，未设置时只使用本地账户认证
+func (h *UserLoginHandler) SetLDAPAuthenticator(authenticator sso.LDAPAuthenticator) {
+	h.ldap = authenticator
+}
+
+// SetInactivityService 设置长期未登录账户处置服务，未设置时不记录最后登录时间，也不恢复策略执行的处置
+func (h *UserLoginHandler) SetInactivityService(service user.InactivityService) {
+	h.inactivity = service
+}
+
+// SetVerificationService 设置验证码服务，未设置时不提供短信验证码登录和短信两步验证
+func (h *UserLoginHandler) SetVerificationService(service verification.VerificationService) {
+	h.codes = service
+}
+
+// SetLoginChallengeStore 设置登录挑战存储，未设置时使用全局登录挑战存储
+func (h *UserLoginHandler) SetLoginChallengeStore(store cache.LoginChallengeStore) {
+	h.challenges = store
+}
+
 // Login 用户登录
 //
 // @Summary 用户登录
 // @Description 支持邮箱、用户名登录，返回JWT访问令牌和刷新令牌
+// @Description 启用LDAP时先在企业目录中验证，按所属组映射角色和团队，首次登录可自动开通账户；目录中没有该用户或目录不可用时回退到本地账户认证
 // @Tags 认证
 // @Accept json
 // @Produce json
 // @Param request body LoginRequest true "登录请求"
 // @Success 200 {object} utils.Response{data=LoginResponse} "登录成功"
 // @Failure 400 {object} utils.Response "请求参数错误"
-// @Failure 401 {object} utils.Response "认证失败"
+// @Failure 401 {object} utils.Response{data=TwoFactorChallengeInfo} "认证失败；已启用两步验证时返回挑战令牌(code=1027)，调用两步验证登录接口提交验证码"
+// @Failure 403 {object} utils.Response{data=PendingDeletionInfo} "账户已计划删除(code=1026)，可调用重新激活接口恢复；邮箱域名已开启强制单点登录时返回code=1029，data为sso.Discovery"
 // @Failure 429 {object} utils.Response "请求频率限制"
 // @Failure 500 {object} utils.Response "内部服务器错误"
 // @Router /api/v1/login [post]
 func (h *UserLoginHandler) Login(c *gin.Context) {
-	ctx := c.Request.Context()
-
 	// 解析请求参数
 	var req LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -113,41 +223,28 @@ func (h *UserLoginHandler) Login(c *gin.Context) {
 		return
 	}
 
-	// 验证请求参数
-	if err := h.validateLoginRequest(&req); err != nil {
-		h.logger.Warn("Login request validation failed",
-			zap.String("identifier", req.Identifier),
-			zap.String("login_type", req.LoginType),
-			zap.Error(err),
-			zap.String("ip", c.ClientIP()))
-		utils.ErrorWithMessage(c, utils.CodeValidationError, err.Error())
-		return
-	}
-
-	// 根据登录类型查找用户
-	user, err := h.findUserByIdentifier(ctx, req.Identifier, req.LoginType)
-	if err != nil {
-		h.logger.Warn("User not found during login",
-			zap.String("identifier", req.Identifier),
-			zap.String("login_type", req.LoginType),
-			zap.Error(err),
-			zap.String("ip", c.ClientIP()))
-		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户名或密码错误")
+	user, role, ok := h.authenticate(c, &req)
+	if !ok {
 		return
 	}
+	h.completeLogin(c, user, role, req.RememberMe)
+}
 
-	// 验证密码
-	if !utils.VerifyPassword(user.PasswordHash, req.Password) {
-		h.logger.Warn("Password verification failed",
+// completeLogin 凭据验证通过后检查账户状态和两步验证并以 role 签发令牌，密码登录和短信验证码登录共用
+func (h *UserLoginHandler) completeLogin(c *gin.Context, user *models.User, role string, rememberMe bool) {
+	// 计划删除的账户返回专用错误码，客户端可引导用户重新激活
+	if user.IsPendingDeletion(time.Now()) {
+		h.logger.Info("Login to account pending deletion",
 			zap.Uint("user_id", user.ID),
-			zap.String("identifier", req.Identifier),
+			zap.Time("deletion_scheduled_at", *user.DeletionScheduledAt),
 			zap.String("ip", c.ClientIP()))
-		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户名或密码错误")
+		utils.ErrorWithData(c, utils.CodePendingDeletion, "账户已计划删除，重新激活后可继续使用",
+			PendingDeletionInfo{DeletionScheduledAt: user.DeletionScheduledAt.Format(time.RFC3339)})
 		return
 	}
 
 	// 检查用户状态
-	if err := h.checkUserStatus(user); err != nil {
+	if err := h.checkLoginStatus(c, user); err != nil {
 		h.logger.Warn("User status check failed",
 			zap.Uint("user_id", user.ID),
 			zap.String("status", user.Status),
@@ -157,31 +254,176 @@ func (h *UserLoginHandler) Login(c *gin.Context) {
 		return
 	}
 
-	// 生成JWT令牌
-	response, err := h.generateTokens(user, req.RememberMe)
-	if err != nil {
-		h.logger.Error("Failed to generate tokens",
+	if h.challengeSecondFactor(c, user, role, rememberMe) {
+		return
+	}
+
+	if response, ok := h.issueTokens(c, user, role, rememberMe); ok {
+		// 记录登录成功日志
+		h.logger.Info("User login successful",
 			zap.Uint("user_id", user.ID),
-			zap.Error(err),
+			zap.String("username", user.Username),
+			zap.String("email", user.Email),
 			zap.String("ip", c.ClientIP()))
-		utils.InternalErrorWithMessage(c, "令牌生成失败")
+
+		utils.SuccessWithMessage(c, "登录成功", response)
+	}
+}
+
+// Reactivate 重新激活计划删除的账户
+//
+// @Summary 重新激活账户
+// @Description 使用登录凭据取消账户的计划删除，恢复正常状态并直接登录。只能在计划删除时间之前重新激活
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param request body LoginRequest true "登录凭据"
+// @Success 200 {object} utils.Response{data=LoginResponse} "账户已恢复"
+// @Failure 400 {object} utils.Response "账户未计划删除或已超过恢复期限"
+// @Failure 401 {object} utils.Response{data=TwoFactorChallengeInfo} "认证失败；已启用两步验证时账户已恢复并返回挑战令牌(code=1027)"
+// @Failure 500 {object} utils.Response "内部服务器错误"
+// @Router /api/v1/auth/reactivate [post]
+func (h *UserLoginHandler) Reactivate(c *gin.Context) {
+	var req LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
 		return
 	}
 
-	// 记录登录成功日志
-	h.logger.Info("User login successful",
+	user, role, ok := h.authenticate(c, &req)
+	if !ok {
+		return
+	}
+	if !user.IsPendingDeletion(time.Now()) {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "账户未计划删除或已超过恢复期限")
+		return
+	}
+
+	if err := h.userService.CancelDeletion(c.Request.Context(), user.ID); err != nil {
+		if errors.Is(err, pkgErrors.ErrOperationNotAllowed) {
+			utils.ErrorWithMessage(c, utils.CodeBadRequest, "账户未计划删除或已超过恢复期限")
+			return
+		}
+		h.logger.Error("Failed to cancel account deletion",
+			zap.Uint("user_id", user.ID),
+			zap.Error(err),
+			zap.String("ip", c.ClientIP()))
+		utils.InternalErrorWithMessage(c, "恢复账户失败")
+		return
+	}
+	user.Status = "active"
+	user.DeletionScheduledAt = nil
+	h.logger.Info("Account reactivated",
 		zap.Uint("user_id", user.ID),
-		zap.String("username", user.Username),
-		zap.String("email", user.Email),
 		zap.String("ip", c.ClientIP()))
 
-	utils.SuccessWithMessage(c, "登录成功", response)
+	// 账户已恢复，仍需满足正常登录的状态要求(如强制重置密码)
+	if err := h.checkUserStatus(user); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, err.Error())
+		return
+	}
+
+	if h.challengeSecondFactor(c, user, role, req.RememberMe) {
+		return
+	}
+
+	if response, ok := h.issueTokens(c, user, role, req.RememberMe); ok {
+		utils.SuccessWithMessage(c, "账户已恢复", response)
+	}
+}
+
+// VerifyTwoFactor 两步验证登录
+//
+// @Summary 两步验证登录
+// @Description 登录返回需要两步验证(code=1027)后，提交挑战令牌和验证器App上的验证码或备用码完成登录。
+// @Description 已绑定并验证手机号的用户可先调用发送两步验证短信接口，再以method=sms提交短信验证码。验证码连续错误5次后挑战失效，需要重新登录
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param request body TwoFactorLoginRequest true "两步验证登录请求"
+// @Success 200 {object} utils.Response{data=LoginResponse} "登录成功"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "验证码错误或挑战已过期"
+// @Failure 429 {object} utils.Response "验证码错误次数过多"
+// @Failure 500 {object} utils.Response "内部服务器错误"
+// @Router /api/v1/auth/2fa/verify [post]
+func (h *UserLoginHandler) VerifyTwoFactor(c *gin.Context) {
+	var req TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+		return
+	}
+
+	store := h.loginChallengeStore()
+	if h.twoFactor == nil || store == nil {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "验证已过期，请重新登录")
+		return
+	}
+
+	ctx := c.Request.Context()
+	challenge, err := store.Get(ctx, req.ChallengeToken)
+	if err != nil {
+		if !errors.Is(err, cache.ErrLoginChallengeNotFound) {
+			h.logger.Error("Failed to get login challenge", zap.Error(err))
+			utils.InternalErrorWithMessage(c, "两步验证失败")
+			return
+		}
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "验证已过期，请重新登录")
+		return
+	}
+	userID := uint(challenge.UserID)
+
+	if err := h.verifySecondFactor(ctx, userID, req.Method, req.Code); err != nil {
+		if !errors.Is(err, user.ErrInvalidTwoFactorCode) {
+			h.logger.Error("Failed to verify two-factor code", zap.Uint("user_id", userID), zap.Error(err))
+			utils.InternalErrorWithMessage(c, "两步验证失败")
+			return
+		}
+		h.respondTwoFactorFailure(c, store, challenge)
+		return
+	}
+
+	// 挑战只能使用一次，并发提交时只有删除成功的请求签发令牌
+	deleted, err := store.Delete(ctx, challenge.ID)
+	if err != nil {
+		h.logger.Error("Failed to delete login challenge", zap.Uint("user_id", userID), zap.Error(err))
+		utils.InternalErrorWithMessage(c, "两步验证失败")
+		return
+	}
+	if !deleted {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "验证已过期，请重新登录")
+		return
+	}
+
+	// 挑战有效期内账户状态可能已变化(如被禁用或强制重置密码)
+	account, err := h.userService.GetUserByID(ctx, userID)
+	if err != nil {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户不存在")
+		return
+	}
+	if err := h.checkLoginStatus(c, account); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, err.Error())
+		return
+	}
+
+	role := challenge.Role
+	if role == "" {
+		role = defaultRole
+	}
+	if response, ok := h.issueTokens(c, account, role, challenge.RememberMe); ok {
+		h.logger.Info("User login successful",
+			zap.Uint("user_id", account.ID),
+			zap.String("username", account.Username),
+			zap.Bool("two_factor", true),
+			zap.String("ip", c.ClientIP()))
+		utils.SuccessWithMessage(c, "登录成功", response)
+	}
 }
 
 // RefreshToken 刷新访问令牌
 //
 // @Summary 刷新访问令牌
-// @Description 使用刷新令牌获取新的访问令牌
+// @Description 使用刷新令牌获取新的访问令牌和刷新令牌。每个刷新令牌只能使用一次，已使用过的刷新令牌再次提交时视为令牌泄露，该用户的全部令牌都会失效
 // @Tags 认证
 // @Accept json
 // @Produce json
@@ -207,6 +449,13 @@ func (h *UserLoginHandler) RefreshToken(c *gin.Context) {
 		return
 	}
 
+	// 已吊销的刷新令牌(如管理员强制重置密码)不能换取新令牌
+	if h.isRefreshTokenRevoked(ctx, req.RefreshToken) {
+		h.logger.Warn("Revoked refresh token rejected", zap.String("ip", c.ClientIP()))
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "刷新令牌已失效，请重新登录")
+		return
+	}
+
 	// 刷新令牌
 	newAccessToken, newRefreshToken, err := h.jwtManager.RefreshToken(req.RefreshToken)
 	if err != nil {
@@ -223,6 +472,13 @@ func (h *UserLoginHandler) RefreshToken(c *gin.Context) {
 		return
 	}
 
+	// 轮换刷新令牌，旧令牌随即失效
+	if err := h.rotateRefreshToken(ctx, req.RefreshToken, claims); err != nil {
+		h.respondRotateError(c, claims.UserID, err)
+		return
+	}
+	h.recordSession(c, req.RefreshToken, newRefreshToken)
+
 	// 获取用户信息
 	user, err := h.userService.GetUserByID(ctx, uint(claims.UserID))
 	if err != nil {
@@ -234,7 +490,7 @@ func (h *UserLoginHandler) RefreshToken(c *gin.Context) {
 		return
 	}
 
-	// 检查用户状态
+	// 检查用户状态，刷新令牌不是登录，不恢复长期未登录策略执行的处置
 	if err := h.checkUserStatus(user); err != nil {
 		h.logger.Warn("User status check failed during token refresh",
 			zap.Uint("user_id", user.ID),
@@ -251,7 +507,7 @@ func (h *UserLoginHandler) RefreshToken(c *gin.Context) {
 		RefreshToken: newRefreshToken,
 		TokenType:    "Bearer",
 		ExpiresIn:    int64(24 * time.Hour.Seconds()), // 24小时
-		User:         h.buildUserInfo(user),
+		User:         h.buildUserInfo(user, claims.Role),
 	}
 
 	h.logger.Info("Token refresh successful",
@@ -262,6 +518,400 @@ func (h *UserLoginHandler) RefreshToken(c *gin.Context) {
 	utils.SuccessWithMessage(c, "令牌刷新成功", response)
 }
 
+// Logout 用户登出
+//
+// @Summary 用户登出
+// @Description 吊销提交的刷新令牌并结束对应的登录会话，令牌无效或已失效时同样返回成功。已签发的访问令牌在过期前仍然有效，客户端应自行丢弃
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param request body RefreshTokenRequest true "要吊销的刷新令牌"
+// @Success 200 {object} utils.Response "登出成功"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 500 {object} utils.Response "内部服务器错误"
+// @Router /api/v1/auth/logout [post]
+func (h *UserLoginHandler) Logout(c *gin.Context) {
+	var req RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+		return
+	}
+
+	claims, err := h.jwtManager.ValidateToken(req.RefreshToken)
+	if err != nil || claims.TokenType != "refresh" {
+		utils.SuccessWithMessage(c, "登出成功", nil)
+		return
+	}
+
+	if store := h.refreshTokenStore(); store != nil {
+		if err := store.Revoke(c.Request.Context(), claims.UserID, claims.ID); err != nil {
+			h.logger.Error("Failed to revoke refresh token",
+				zap.Uint64("user_id", claims.UserID),
+				zap.Error(err),
+				zap.String("ip", c.ClientIP()))
+			utils.InternalErrorWithMessage(c, "登出失败")
+			return
+		}
+	}
+
+	if store := h.sessionStore(); store != nil {
+		session, err := cache.FindSessionByRefreshToken(c.Request.Context(), store, claims.UserID, claims.ID)
+		if err == nil {
+			_, err = store.Delete(c.Request.Context(), claims.UserID, session.ID)
+		}
+		if err != nil && !errors.Is(err, cache.ErrSessionNotFound) {
+			h.logger.Warn("Failed to remove session on logout",
+				zap.Uint64("user_id", claims.UserID),
+				zap.Error(err))
+		}
+	}
+
+	h.logger.Info("User logout successful",
+		zap.Uint64("user_id", claims.UserID),
+		zap.String("ip", c.ClientIP()))
+	utils.SuccessWithMessage(c, "登出成功", nil)
+}
+
+// ListSessions 列出当前用户的登录会话
+//
+// @Summary 列出登录会话
+// @Description 返回当前用户未过期的登录会话(设备、IP、最后活跃时间)，按最后活跃时间倒序。刷新令牌已被吊销的会话不再返回
+// @Tags 认证
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response{data=[]SessionInfo} "获取成功"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 500 {object} utils.Response "内部服务器错误"
+// @Router /api/v1/auth/sessions [get]
+func (h *UserLoginHandler) ListSessions(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	infos := []SessionInfo{}
+	store := h.sessionStore()
+	if store == nil {
+		utils.Success(c, infos)
+		return
+	}
+
+	ctx := c.Request.Context()
+	sessions, err := store.List(ctx, uint64(userID))
+	if err != nil {
+		h.logger.Error("Failed to list sessions", zap.Uint("user_id", userID), zap.Error(err))
+		utils.InternalErrorWithMessage(c, "获取登录会话失败")
+		return
+	}
+	for _, session := range sessions {
+		if !h.sessionActive(ctx, store, session) {
+			continue
+		}
+		infos = append(infos, SessionInfo{
+			ID:        session.ID,
+			Device:    session.Device,
+			IP:        session.IP,
+			CreatedAt: session.CreatedAt.Format(time.RFC3339),
+			LastSeen:  session.LastSeen.Format(time.RFC3339),
+			ExpiresAt: session.ExpiresAt.Format(time.RFC3339),
+		})
+	}
+	utils.Success(c, infos)
+}
+
+// RevokeSession 结束当前用户的单个登录会话
+//
+// @Summary 结束登录会话
+// @Description 删除登录会话并吊销其刷新令牌，该设备无法再刷新令牌。已签发的访问令牌在过期前仍然有效
+// @Tags 认证
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "会话ID"
+// @Success 200 {object} utils.Response "会话已结束"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 404 {object} utils.Response "会话不存在"
+// @Failure 500 {object} utils.Response "内部服务器错误"
+// @Router /api/v1/auth/sessions/{id} [delete]
+func (h *UserLoginHandler) RevokeSession(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户身份验证失败")
+		return
+	}
+
+	store := h.sessionStore()
+	if store == nil {
+		utils.ErrorWithMessage(c, utils.CodeNotFound, "会话不存在")
+		return
+	}
+
+	ctx := c.Request.Context()
+	session, err := store.Delete(ctx, uint64(userID), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, cache.ErrSessionNotFound) {
+			utils.ErrorWithMessage(c, utils.CodeNotFound, "会话不存在")
+			return
+		}
+		h.logger.Error("Failed to delete session", zap.Uint("user_id", userID), zap.Error(err))
+		utils.InternalErrorWithMessage(c, "结束会话失败")
+		return
+	}
+
+	if refreshStore := h.refreshTokenStore(); refreshStore != nil {
+		if err := refreshStore.Revoke(ctx, uint64(userID), session.RefreshJTI); err != nil {
+			h.logger.Error("Failed to revoke session refresh token",
+				zap.Uint("user_id", userID),
+				zap.String("session_id", session.ID),
+				zap.Error(err))
+			utils.InternalErrorWithMessage(c, "结束会话失败")
+			return
+		}
+	}
+
+	h.logger.Info("Session revoked",
+		zap.Uint("user_id", userID),
+		zap.String("session_id", session.ID),
+		zap.String("ip", c.ClientIP()))
+	utils.SuccessWithMessage(c, "会话已结束", nil)
+}
+
+// authenticate 校验登录请求并验证用户凭据，返回用户和签发令牌的角色，失败时写入错误响应并返回false
+//
+// 启用LDAP时先在目录中验证，目录中没有该用户或目录不可用时回退到本地账户认证
+func (h *UserLoginHandler) authenticate(c *gin.Context, req *LoginRequest) (*models.User, string, bool) {
+	// 验证请求参数
+	if err := h.validateLoginRequest(req); err != nil {
+		h.logger.Warn("Login request validation failed",
+			zap.String("identifier", req.Identifier),
+			zap.String("login_type", req.LoginType),
+			zap.Error(err),
+			zap.String("ip", c.ClientIP()))
+		utils.ErrorWithMessage(c, utils.CodeValidationError, err.Error())
+		return nil, "", false
+	}
+
+	if h.ldap != nil {
+		user, role, handled := h.authenticateLDAP(c, req)
+		if handled {
+			if user == nil || h.requireSSO(c, user) {
+				return nil, "", false
+			}
+			return user, role, true
+		}
+	}
+
+	// 根据登录类型查找用户
+	user, err := h.findUserByIdentifier(c.Request.Context(), req.Identifier, req.LoginType)
+	if err != nil {
+		h.logger.Warn("User not found during login",
+			zap.String("identifier", req.Identifier),
+			zap.String("login_type", req.LoginType),
+			zap.Error(err),
+			zap.String("ip", c.ClientIP()))
+		h.recordLoginFailure(c, 0, "user_not_found", map[string]interface{}{"identifier": req.Identifier})
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户名或密码错误")
+		return nil, "", false
+	}
+
+	// 验证密码
+	if !utils.VerifyPassword(user.PasswordHash, req.Password) {
+		h.logger.Warn("Password verification failed",
+			zap.Uint("user_id", user.ID),
+			zap.String("identifier", req.Identifier),
+			zap.String("ip", c.ClientIP()))
+		h.recordLoginFailure(c, user.ID, "invalid_password", nil)
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户名或密码错误")
+		return nil, "", false
+	}
+
+	// 凭据正确后才检查强制SSO，避免未认证的请求借此探测账户
+	if h.requireSSO(c, user) {
+		return nil, "", false
+	}
+
+	return user, defaultRole, true
+}
+
+// authenticateLDAP 在企业目录中验证凭据，handled为false时调用方回退到本地账户认证
+//
+// handled为true且用户为nil时已写入错误响应
+func (h *UserLoginHandler) authenticateLDAP(c *gin.Context, req *LoginRequest) (*models.User, string, bool) {
+	result, err := h.ldap.Authenticate(c.Request.Context(), req.Identifier, req.Password)
+	switch {
+	case err == nil:
+		return result.User, result.Role, true
+	case errors.Is(err, sso.ErrLDAPUserNotFound):
+		return nil, "", false
+	case errors.Is(err, sso.ErrLDAPUnavailable):
+		h.logger.Warn("LDAP unavailable, falling back to local authentication",
+			zap.String("identifier", req.Identifier),
+			zap.Error(err),
+			zap.String("ip", c.ClientIP()))
+		return nil, "", false
+	case errors.Is(err, sso.ErrLoginFailed):
+		h.logger.Warn("LDAP authentication failed",
+			zap.String("identifier", req.Identifier),
+			zap.Error(err),
+			zap.String("ip", c.ClientIP()))
+		h.recordLoginFailure(c, 0, "ldap_rejected", map[string]interface{}{"identifier": req.Identifier})
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, err.Error())
+		return nil, "", true
+	default:
+		h.logger.Error("LDAP authentication error", zap.String("identifier", req.Identifier), zap.Error(err))
+		respondServiceError(c, err, "登录失败")
+		return nil, "", true
+	}
+}
+
+// challengeSecondFactor 用户已启用两步验证时创建登录挑战并写入响应，返回true表示已响应
+//
+// 两步验证状态以登记表为准，不依赖用户表上可能被缓存的 mfa_enabled 字段
+func (h *UserLoginHandler) challengeSecondFactor(c *gin.Context, account *models.User, role string, rememberMe bool) bool {
+	if h.twoFactor == nil {
+		return false
+	}
+	ctx := c.Request.Context()
+	enabled, err := h.twoFactor.IsEnabled(ctx, account.ID)
+	if err != nil {
+		h.logger.Error("Failed to check two-factor status", zap.Uint("user_id", account.ID), zap.Error(err))
+		utils.InternalErrorWithMessage(c, "登录失败")
+		return true
+	}
+	if !enabled {
+		return false
+	}
+
+	store := h.loginChallengeStore()
+	if store == nil {
+		// 已启用两步验证的账户不能跳过第二步
+		h.logger.Error("Login challenge store not configured", zap.Uint("user_id", account.ID))
+		utils.InternalErrorWithMessage(c, "登录失败")
+		return true
+	}
+	id, err := utils.GenerateHex(loginChallengeIDLength)
+	if err != nil {
+		h.logger.Error("Failed to generate login challenge ID", zap.Error(err))
+		utils.InternalErrorWithMessage(c, "登录失败")
+		return true
+	}
+	challenge := &cache.LoginChallenge{
+		ID:         id,
+		UserID:     uint64(account.ID),
+		RememberMe: rememberMe,
+		Role:       role,
+		ExpiresAt:  time.Now().Add(loginChallengeTTL),
+	}
+	if err := store.Save(ctx, challenge); err != nil {
+		h.logger.Error("Failed to save login challenge", zap.Uint("user_id", account.ID), zap.Error(err))
+		utils.InternalErrorWithMessage(c, "登录失败")
+		return true
+	}
+
+	h.logger.Info("Two-factor challenge issued",
+		zap.Uint("user_id", account.ID),
+		zap.String("ip", c.ClientIP()))
+	utils.ErrorWithData(c, utils.CodeTwoFactorRequired, "请输入两步验证码",
+		TwoFactorChallengeInfo{ChallengeToken: id, ExpiresAt: challenge.ExpiresAt.Format(time.RFC3339)})
+	return true
+}
+
+// respondTwoFactorFailure 记录验证码错误，达到次数上限时作废挑战
+func (h *UserLoginHandler) respondTwoFactorFailure(c *gin.Context, store cache.LoginChallengeStore, challenge *cache.LoginChallenge) {
+	ctx := c.Request.Context()
+	attempts, err := store.RecordFailure(ctx, challenge.ID)
+	if err != nil {
+		if errors.Is(err, cache.ErrLoginChallengeNotFound) {
+			utils.ErrorWithMessage(c, utils.CodeUnauthorized, "验证已过期，请重新登录")
+			return
+		}
+		h.logger.Error("Failed to record two-factor failure", zap.Uint64("user_id", challenge.UserID), zap.Error(err))
+		utils.InternalErrorWithMessage(c, "两步验证失败")
+		return
+	}
+
+	h.logger.Warn("Two-factor verification failed",
+		zap.Uint64("user_id", challenge.UserID),
+		zap.Int("attempts", attempts),
+		zap.String("ip", c.ClientIP()))
+	h.recordLoginFailure(c, uint(challenge.UserID), "invalid_two_factor_code", map[string]interface{}{"attempts": attempts})
+	if attempts >= loginChallengeMaxAttempts {
+		if _, err := store.Delete(ctx, challenge.ID); err != nil {
+			h.logger.Error("Failed to delete login challenge", zap.Uint64("user_id", challenge.UserID), zap.Error(err))
+		}
+		utils.ErrorWithMessage(c, utils.CodeTooManyRequests, "验证码错误次数过多，请重新登录")
+		return
+	}
+	utils.ErrorWithMessage(c, utils.CodeUnauthorized, "验证码错误")
+}
+
+// loginChallengeStore 返回登录挑战存储，未配置时返回nil
+func (h *UserLoginHandler) loginChallengeStore() cache.LoginChallengeStore {
+	if h.challenges != nil {
+		return h.challenges
+	}
+	return cache.DefaultLoginChallengeStore()
+}
+
+// issueTokens 为通过认证的用户记录登录、签发令牌、登记刷新令牌并记录登录会话，失败时写入错误响应并返回false
+//
+// role 写入令牌，刷新令牌时沿用；密码登录使用 defaultRole，单点登录使用身份提供方映射的角色
+func (h *UserLoginHandler) issueTokens(c *gin.Context, user *models.User, role string, rememberMe bool) (*LoginResponse, bool) {
+	// 调用方已完成状态检查和两步验证，此时才算登录成功
+	h.recordLogin(c, user)
+
+	// 生成JWT令牌
+	response, err := h.generateTokens(user, role, rememberMe)
+	if err != nil {
+		h.logger.Error("Failed to generate tokens",
+			zap.Uint("user_id", user.ID),
+			zap.Error(err),
+			zap.String("ip", c.ClientIP()))
+		utils.InternalErrorWithMessage(c, "令牌生成失败")
+		return nil, false
+	}
+
+	// 登记刷新令牌，登记失败时签发的刷新令牌无法使用，直接返回错误
+	if err := h.issueRefreshToken(c.Request.Context(), response.RefreshToken); err != nil {
+		h.logger.Error("Failed to register refresh token",
+			zap.Uint("user_id", user.ID),
+			zap.Error(err),
+			zap.String("ip", c.ClientIP()))
+		utils.InternalErrorWithMessage(c, "令牌生成失败")
+		return nil, false
+	}
+	h.recordSession(c, "", response.RefreshToken)
+	h.recordSecurity(c, h.logger, &audit.Event{
+		UserID:       user.ID,
+		Action:       audit.SecurityActionLogin,
+		ResourceType: audit.ResourceUser,
+		ResourceID:   fmt.Sprint(user.ID),
+		ResourceName: user.Username,
+		Details:      map[string]interface{}{"role": role, "remember_me": rememberMe},
+	})
+
+	return response, true
+}
+
+// recordLoginFailure 记录登录失败的安全审计，用户不存在时userID为0
+func (h *UserLoginHandler) recordLoginFailure(c *gin.Context, userID uint, reason string, details map[string]interface{}) {
+	if details == nil {
+		details = map[string]interface{}{}
+	}
+	details["reason"] = reason
+	event := &audit.Event{
+		UserID:       userID,
+		Action:       audit.SecurityActionLoginFailed,
+		ResourceType: audit.ResourceUser,
+		Result:       models.AuditResultFailure,
+		Details:      details,
+	}
+	if userID != 0 {
+		event.ResourceID = fmt.Sprint(userID)
+	}
+	h.recordSecurity(c, h.logger, event)
+}
+
 // validateLoginRequest 验证登录请求参数
 func (h *UserLoginHandler) validateLoginRequest(req *LoginRequest) error {
 	// 验证登录标识符
@@ -323,12 +973,65 @@ func (h *UserLoginHandler) findUserByIdentifier(ctx context.Context, identifier,
 	}
 }
 
+// recordLogin 登录成功后记录登录时间，恢复长期未登录策略降低的配额和冻结的账户
+//
+// 只在签发令牌时调用：凭据验证、状态检查和两步验证都已通过；失败时只记录日志，不影响登录
+func (h *UserLoginHandler) recordLogin(c *gin.Context, account *models.User) {
+	if h.inactivity == nil {
+		return
+	}
+	restored, err := h.inactivity.RecordLogin(c.Request.Context(), account, c.ClientIP())
+	if err != nil {
+		h.logger.Warn("Failed to record login activity",
+			zap.Uint("user_id", account.ID),
+			zap.Error(err),
+			zap.String("ip", c.ClientIP()))
+		return
+	}
+	if restored {
+		h.logger.Info("Inactive account restored on login",
+			zap.Uint("user_id", account.ID),
+			zap.String("ip", c.ClientIP()))
+	}
+}
+
+// checkLoginStatus 凭据验证通过后检查用户状态
+//
+// 被长期未登录策略冻结的账户按正常状态检查，登录成功签发令牌时才解除冻结(见 recordLogin)
+func (h *UserLoginHandler) checkLoginStatus(c *gin.Context, account *models.User) error {
+	if account.Status == "inactive" && h.isFrozenByInactivity(c, account) {
+		active := *account
+		active.Status = "active"
+		return h.checkUserStatus(&active)
+	}
+	return h.checkUserStatus(account)
+}
+
+// isFrozenByInactivity 账户是否被长期未登录策略冻结，查询失败时按管理员禁用处理
+func (h *UserLoginHandler) isFrozenByInactivity(c *gin.Context, account *models.User) bool {
+	if h.inactivity == nil {
+		return false
+	}
+	frozen, err := h.inactivity.IsFrozen(c.Request.Context(), account)
+	if err != nil {
+		h.logger.Warn("Failed to check inactivity freeze",
+			zap.Uint("user_id", account.ID),
+			zap.Error(err),
+			zap.String("ip", c.ClientIP()))
+		return false
+	}
+	return frozen
+}
+
 // checkUserStatus 检查用户状态
 func (h *UserLoginHandler) checkUserStatus(user *models.User) error {
 	switch user.Status {
 	case "inactive": // 已禁用
 		return fmt.Errorf("用户账户已被禁用")
 	case "active": // 正常
+		if user.PasswordResetRequired {
+			return fmt.Errorf("账户需要重置密码，请通过忘记密码设置新密码后登录")
+		}
 		return nil
 	case "suspended": // 已暂停
 		return fmt.Errorf("用户账户已被暂停，请联系客服")
@@ -339,14 +1042,197 @@ func (h *UserLoginHandler) checkUserStatus(user *models.User) error {
 	}
 }
 
+// isRefreshTokenRevoked 检查刷新令牌是否已被吊销
+//
+// 令牌本身无效时返回false，由后续刷新流程返回具体错误；吊销存储不可用时放行
+func (h *UserLoginHandler) isRefreshTokenRevoked(ctx context.Context, refreshToken string) bool {
+	store := h.tokenStore
+	if store == nil {
+		store = cache.DefaultTokenStore()
+	}
+	if store == nil {
+		return false
+	}
+
+	claims, err := h.jwtManager.ValidateToken(refreshToken)
+	if err != nil || claims.IssuedAt == nil {
+		return false
+	}
+	revoked, err := cache.IsTokenRevoked(ctx, store, claims.UserID, claims.IssuedAt.Time)
+	if err != nil {
+		h.logger.Error("Failed to check token revocation",
+			zap.Uint64("user_id", claims.UserID),
+			zap.Error(err))
+		return false
+	}
+	return revoked
+}
+
+// refreshTokenStore 返回刷新令牌登记存储，未配置时返回nil表示不启用轮换
+func (h *UserLoginHandler) refreshTokenStore() cache.RefreshTokenStore {
+	if h.refreshStore != nil {
+		return h.refreshStore
+	}
+	return cache.DefaultRefreshTokenStore()
+}
+
+// sessionStore 返回登录会话存储，未配置时返回nil表示不记录会话
+func (h *UserLoginHandler) sessionStore() cache.SessionStore {
+	if h.sessions != nil {
+		return h.sessions
+	}
+	return cache.DefaultSessionStore()
+}
+
+// recordSession 记录登录会话，oldToken为空表示新登录，否则将刷新前的会话更新为新刷新令牌
+//
+// 会话只用于展示和管理，记录失败不影响登录和刷新；
+// 刷新时找不到会话(如会话功能启用前签发的令牌)按新登录创建会话
+func (h *UserLoginHandler) recordSession(c *gin.Context, oldToken, newToken string) {
+	store := h.sessionStore()
+	if store == nil {
+		return
+	}
+	ctx := c.Request.Context()
+
+	claims, err := h.jwtManager.ValidateToken(newToken)
+	if err != nil {
+		h.logger.Warn("Failed to parse refresh token for session", zap.Error(err))
+		return
+	}
+
+	now := time.Now()
+	var session *cache.Session
+	if oldToken != "" {
+		if oldClaims, err := h.jwtManager.ValidateToken(oldToken); err == nil {
+			session, err = cache.FindSessionByRefreshToken(ctx, store, claims.UserID, oldClaims.ID)
+			if err != nil && !errors.Is(err, cache.ErrSessionNotFound) {
+				h.logger.Warn("Failed to find session", zap.Uint64("user_id", claims.UserID), zap.Error(err))
+			}
+		}
+	}
+	if session == nil {
+		id, err := utils.GenerateHex(sessionIDLength)
+		if err != nil {
+			h.logger.Warn("Failed to generate session id", zap.Error(err))
+			return
+		}
+		session = &cache.Session{ID: id, UserID: claims.UserID, CreatedAt: now}
+	}
+
+	session.RefreshJTI = claims.ID
+	session.Device = utils.Truncate(c.Request.UserAgent(), maxSessionDeviceLength)
+	session.IP = c.ClientIP()
+	session.LastSeen = now
+	session.ExpiresAt = claims.ExpiresAt.Time
+	if err := store.Save(ctx, session); err != nil {
+		h.logger.Warn("Failed to save session",
+			zap.Uint64("user_id", claims.UserID),
+			zap.Error(err))
+	}
+}
+
+// sessionActive 检查会话的刷新令牌是否仍然可用，已吊销(如修改密码)的会话顺带删除
+//
+// 刷新令牌登记存储未配置或不可用时视为可用
+func (h *UserLoginHandler) sessionActive(ctx context.Context, store cache.SessionStore, session *cache.Session) bool {
+	refreshStore := h.refreshTokenStore()
+	if refreshStore == nil {
+		return true
+	}
+	active, err := refreshStore.Active(ctx, session.UserID, session.RefreshJTI)
+	if err != nil {
+		h.logger.Warn("Failed to check session refresh token",
+			zap.Uint64("user_id", session.UserID),
+			zap.String("session_id", session.ID),
+			zap.Error(err))
+		return true
+	}
+	if !active {
+		if _, err := store.Delete(ctx, session.UserID, session.ID); err != nil && !errors.Is(err, cache.ErrSessionNotFound) {
+			h.logger.Warn("Failed to remove revoked session",
+				zap.Uint64("user_id", session.UserID),
+				zap.String("session_id", session.ID),
+				zap.Error(err))
+		}
+	}
+	return active
+}
+
+// issueRefreshToken 登记登录时签发的刷新令牌
+func (h *UserLoginHandler) issueRefreshToken(ctx context.Context, refreshToken string) error {
+	store := h.refreshTokenStore()
+	if store == nil {
+		return nil
+	}
+
+	claims, err := h.jwtManager.ValidateToken(refreshToken)
+	if err != nil {
+		return fmt.Errorf("解析刷新令牌失败: %w", err)
+	}
+	return store.Issue(ctx, claims.UserID, claims.ID, claims.ExpiresAt.Time)
+}
+
+// rotateRefreshToken 将旧刷新令牌轮换为新签发的刷新令牌
+func (h *UserLoginHandler) rotateRefreshToken(ctx context.Context, oldToken string, newClaims *utils.JWTClaims) error {
+	store := h.refreshTokenStore()
+	if store == nil {
+		return nil
+	}
+
+	oldClaims, err := h.jwtManager.ValidateToken(oldToken)
+	if err != nil {
+		return fmt.Errorf("解析刷新令牌失败: %w", err)
+	}
+	if oldClaims.UserID != newClaims.UserID {
+		return cache.ErrRefreshTokenNotFound
+	}
+	return store.Rotate(ctx, oldClaims.UserID, oldClaims.ID, newClaims.ID, newClaims.ExpiresAt.Time)
+}
+
+// respondRotateError 处理刷新令牌轮换失败
+//
+// 检测到令牌重用时刷新令牌已全部吊销，同时吊销已签发的访问令牌，使攻击者持有的令牌立即失效
+func (h *UserLoginHandler) respondRotateError(c *gin.Context, userID uint64, err error) {
+	switch {
+	case errors.Is(err, cache.ErrRefreshTokenReused):
+		h.logger.Warn("Refresh token reuse detected, revoking all tokens",
+			zap.Uint64("user_id", userID),
+			zap.String("ip", c.ClientIP()))
+		store := h.tokenStore
+		if store == nil {
+			store = cache.DefaultTokenStore()
+		}
+		if store != nil {
+			if err := store.RevokeUserTokens(c.Request.Context(), userID, time.Now()); err != nil {
+				h.logger.Error("Failed to revoke access tokens after refresh token reuse",
+					zap.Uint64("user_id", userID),
+					zap.Error(err))
+			}
+		}
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "刷新令牌已失效，请重新登录")
+	case errors.Is(err, cache.ErrRefreshTokenNotFound):
+		h.logger.Warn("Unknown or revoked refresh token rejected",
+			zap.Uint64("user_id", userID),
+			zap.String("ip", c.ClientIP()))
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "刷新令牌已失效，请重新登录")
+	default:
+		h.logger.Error("Failed to rotate refresh token",
+			zap.Uint64("user_id", userID),
+			zap.Error(err),
+			zap.String("ip", c.ClientIP()))
+		utils.InternalErrorWithMessage(c, "令牌刷新失败")
+	}
+}
+
 // generateTokens 生成JWT令牌
-func (h *UserLoginHandler) generateTokens(user *models.User, rememberMe bool) (*LoginResponse, error) {
+func (h *UserLoginHandler) generateTokens(user *models.User, role string, rememberMe bool) (*LoginResponse, error) {
 	// 生成访问令牌
 	accessToken, err := h.jwtManager.GenerateAccessToken(
 		uint64(user.ID),
 		user.Username,
 		user.Email,
-		"user", // 默认角色
+		role,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("生成访问令牌失败: %w", err)
@@ -357,7 +1243,7 @@ func (h *UserLoginHandler) generateTokens(user *models.User, rememberMe bool) (*
 		uint64(user.ID),
 		user.Username,
 		user.Email,
-		"user",
+		role,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("生成刷新令牌失败: %w", err)
@@ -374,12 +1260,12 @@ func (h *UserLoginHandler) generateTokens(user *models.User, rememberMe bool) (*
 		RefreshToken: refreshToken,
 		TokenType:    "Bearer",
 		ExpiresIn:    expiresIn,
-		User:         h.buildUserInfo(user),
+		User:         h.buildUserInfo(user, role),
 	}, nil
 }
 
 // buildUserInfo 构建用户信息
-func (h *UserLoginHandler) buildUserInfo(user *models.User) *UserInfo {
+func (h *UserLoginHandler) buildUserInfo(user *models.User, role string) *UserInfo {
 	displayName := ""
 	if user.DisplayName != nil {
 		displayName = *user.DisplayName
@@ -411,7 +1297,7 @@ func (h *UserLoginHandler) buildUserInfo(user *models.User) *UserInfo {
 		DisplayName: displayName,
 		Avatar:      avatarURL,
 		Status:      statusInt,
-		Role:        "user", // 默认角色，后续可从用户模型获取
+		Role:        role,
 		CreatedAt:   user.CreatedAt.Format(time.RFC3339),
 	}
 }
@@ -13,8 +13,11 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 
+	"cloudpan/internal/pkg/cache"
+	pkgErrors "cloudpan/internal/pkg/errors"
 	"cloudpan/internal/pkg/utils"
 	"cloudpan/internal/repository/models"
 	"cloudpan/internal/service/user"
@@ -83,6 +86,10 @@ func (m *MockLoginUserService) UpdatePassword(ctx context.Context, userID uint,
 func (m *MockLoginUserService) ActivateUser(ctx context.Context, userID uint) error {
 	return nil
 }
+func (m *MockLoginUserService) CancelDeletion(ctx context.Context, userID uint) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
 func (m *MockLoginUserService) DeactivateUser(ctx context.Context, userID uint) error {
 	return nil
 }
@@ -107,6 +114,9 @@ func (m *MockLoginUserService) GetActiveUsersCount(ctx context.Context) (int64,
 func (m *MockLoginUserService) UpdateStorageUsed(ctx context.Context, userID uint, size int64) error {
 	return nil
 }
+func (m *MockLoginUserService) UpdateStorageQuota(ctx context.Context, userID uint, quota int64) error {
+	return nil
+}
 func (m *MockLoginUserService) CheckStorageQuota(ctx context.Context, userID uint, requiredSize int64) (bool, error) {
 	return false, nil
 }
@@ -396,6 +406,30 @@ func TestUserLoginHandler_Login(t *testing.T) {
 		assert.Equal(t, http.StatusUnauthorized, w.Code)
 		mockUserService.AssertExpectations(t)
 	})
+
+	t.Run("用户被要求重置密码", func(t *testing.T) {
+		mockUserService := &MockLoginUserService{}
+		handler := setupTestLoginHandler(mockUserService)
+		testUser := setupTestUser()
+		testUser.PasswordResetRequired = true
+
+		mockUserService.On("GetUserByEmail", mock.Anything, "test@example.com").Return(testUser, nil)
+
+		reqBody, _ := json.Marshal(LoginRequest{
+			Identifier: "test@example.com",
+			Password:   "testPassword123!",
+		})
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/api/v1/login", bytes.NewBuffer(reqBody))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		handler.Login(c)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), "重置密码")
+		mockUserService.AssertExpectations(t)
+	})
 }
 
 func TestUserLoginHandler_RefreshToken(t *testing.T) {
@@ -561,4 +595,520 @@ func TestUserLoginHandler_RefreshToken(t *testing.T) {
 		assert.Equal(t, http.StatusUnauthorized, w.Code)
 		mockUserService.AssertExpectations(t)
 	})
+	t.Run("刷新令牌已被吊销", func(t *testing.T) {
+		mockUserService := &MockLoginUserService{}
+		handler := setupTestLoginHandler(mockUserService)
+		testUser := setupTestUser()
+
+		refreshToken, err := handler.jwtManager.GenerateRefreshToken(
+			uint64(testUser.ID), testUser.Username, testUser.Email, "user")
+		assert.NoError(t, err)
+
+		store := cache.NewMemoryTokenStore(time.Hour)
+		assert.NoError(t, store.RevokeUserTokens(context.Background(), uint64(testUser.ID), time.Now()))
+		handler.SetTokenStore(store)
+
+		reqBody, _ := json.Marshal(RefreshTokenRequest{RefreshToken: refreshToken})
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/api/v1/refresh", bytes.NewBuffer(reqBody))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		handler.RefreshToken(c)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		mockUserService.AssertNotCalled(t, "GetUserByID", mock.Anything, mock.Anything)
+	})
+	t.Run("刷新令牌轮换与重用检测", func(t *testing.T) {
+		mockUserService := &MockLoginUserService{}
+		handler := setupTestLoginHandler(mockUserService)
+		testUser := setupTestUser()
+		mockUserService.On("GetUserByID", mock.Anything, uint(testUser.ID)).Return(testUser, nil)
+
+		tokenStore := cache.NewMemoryTokenStore(time.Hour)
+		refreshStore := cache.NewMemoryRefreshTokenStore()
+		handler.SetTokenStore(tokenStore)
+		handler.SetRefreshTokenStore(refreshStore)
+
+		refreshToken, err := handler.jwtManager.GenerateRefreshToken(
+			uint64(testUser.ID), testUser.Username, testUser.Email, "user")
+		assert.NoError(t, err)
+		assert.NoError(t, handler.issueRefreshToken(context.Background(), refreshToken))
+
+		// 第一次使用轮换成功
+		w := postRefreshToken(handler, refreshToken)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		// 再次使用已轮换的令牌，视为令牌泄露
+		w = postRefreshToken(handler, refreshToken)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+		// 用户的全部令牌被吊销，包括之前签发的访问令牌
+		revoked, err := cache.IsTokenRevoked(context.Background(), tokenStore, uint64(testUser.ID), time.Now().Add(-time.Minute))
+		assert.NoError(t, err)
+		assert.True(t, revoked)
+	})
+
+	t.Run("未登记的刷新令牌", func(t *testing.T) {
+		mockUserService := &MockLoginUserService{}
+		handler := setupTestLoginHandler(mockUserService)
+		testUser := setupTestUser()
+		handler.SetRefreshTokenStore(cache.NewMemoryRefreshTokenStore())
+
+		refreshToken, err := handler.jwtManager.GenerateRefreshToken(
+			uint64(testUser.ID), testUser.Username, testUser.Email, "user")
+		assert.NoError(t, err)
+
+		w := postRefreshToken(handler, refreshToken)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		mockUserService.AssertNotCalled(t, "GetUserByID", mock.Anything, mock.Anything)
+	})
+}
+
+func TestUserLoginHandler_Logout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockUserService := &MockLoginUserService{}
+	handler := setupTestLoginHandler(mockUserService)
+	testUser := setupTestUser()
+	refreshStore := cache.NewMemoryRefreshTokenStore()
+	handler.SetRefreshTokenStore(refreshStore)
+
+	refreshToken, err := handler.jwtManager.GenerateRefreshToken(
+		uint64(testUser.ID), testUser.Username, testUser.Email, "user")
+	assert.NoError(t, err)
+	assert.NoError(t, handler.issueRefreshToken(context.Background(), refreshToken))
+
+	for _, token := range []string{refreshToken, "invalid-refresh-token"} {
+		reqBody, _ := json.Marshal(RefreshTokenRequest{RefreshToken: token})
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/api/v1/auth/logout", bytes.NewBuffer(reqBody))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		handler.Logout(c)
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+
+	// 登出后的刷新令牌不能再使用
+	w := postRefreshToken(handler, refreshToken)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestUserLoginHandler_PendingDeletion(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	post := func(handle gin.HandlerFunc, password string) (*httptest.ResponseRecorder, utils.Response) {
+		reqBody, _ := json.Marshal(LoginRequest{Identifier: "test@example.com", Password: password})
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/api/v1/auth/login", bytes.NewBuffer(reqBody))
+		c.Request.Header.Set("Content-Type", "application/json")
+		handle(c)
+		return w, decodeShareResponse(t, w)
+	}
+	pendingUser := func(scheduledAt time.Time) *models.User {
+		user := setupTestUser()
+		user.Status = "deleted"
+		user.DeletionScheduledAt = &scheduledAt
+		return user
+	}
+
+	t.Run("登录返回待删除错误码", func(t *testing.T) {
+		mockUserService := &MockLoginUserService{}
+		handler := setupTestLoginHandler(mockUserService)
+		scheduledAt := time.Now().Add(72 * time.Hour).Truncate(time.Second)
+		mockUserService.On("GetUserByEmail", mock.Anything, "test@example.com").Return(pendingUser(scheduledAt), nil)
+
+		w, resp := post(handler.Login, "testPassword123!")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Equal(t, utils.CodePendingDeletion, resp.Code)
+		data, ok := resp.Data.(map[string]interface{})
+		require.True(t, ok)
+		assert.Equal(t, scheduledAt.Format(time.RFC3339), data["deletion_scheduled_at"])
+
+		// 密码错误时不暴露账户状态
+		_, resp = post(handler.Login, "wrongPassword1!")
+		assert.Equal(t, utils.CodeUnauthorized, resp.Code)
+	})
+
+	t.Run("超过恢复期限按已删除处理", func(t *testing.T) {
+		mockUserService := &MockLoginUserService{}
+		handler := setupTestLoginHandler(mockUserService)
+		mockUserService.On("GetUserByEmail", mock.Anything, "test@example.com").Return(pendingUser(time.Now().Add(-time.Hour)), nil)
+
+		_, resp := post(handler.Login, "testPassword123!")
+		assert.Equal(t, utils.CodeUnauthorized, resp.Code)
+		_, resp = post(handler.Reactivate, "testPassword123!")
+		assert.Equal(t, utils.CodeBadRequest, resp.Code)
+		mockUserService.AssertNotCalled(t, "CancelDeletion", mock.Anything, mock.Anything)
+	})
+
+	t.Run("重新激活后登录", func(t *testing.T) {
+		mockUserService := &MockLoginUserService{}
+		handler := setupTestLoginHandler(mockUserService)
+		handler.SetRefreshTokenStore(cache.NewMemoryRefreshTokenStore())
+		mockUserService.On("GetUserByEmail", mock.Anything, "test@example.com").Return(pendingUser(time.Now().Add(time.Hour)), nil)
+		mockUserService.On("CancelDeletion", mock.Anything, uint(1)).Return(nil).Once()
+
+		// 密码错误不能重新激活
+		_, resp := post(handler.Reactivate, "wrongPassword1!")
+		assert.Equal(t, utils.CodeUnauthorized, resp.Code)
+
+		w, resp := post(handler.Reactivate, "testPassword123!")
+		assert.Equal(t, http.StatusOK, w.Code)
+		data, ok := resp.Data.(map[string]interface{})
+		require.True(t, ok)
+		assert.NotEmpty(t, data["access_token"])
+		mockUserService.AssertExpectations(t)
+	})
+
+	t.Run("并发删除后无法重新激活", func(t *testing.T) {
+		mockUserService := &MockLoginUserService{}
+		handler := setupTestLoginHandler(mockUserService)
+		mockUserService.On("GetUserByEmail", mock.Anything, "test@example.com").Return(pendingUser(time.Now().Add(time.Hour)), nil)
+		mockUserService.On("CancelDeletion", mock.Anything, uint(1)).
+			Return(pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "账户未计划删除或已超过恢复期限"))
+
+		_, resp := post(handler.Reactivate, "testPassword123!")
+		assert.Equal(t, utils.CodeBadRequest, resp.Code)
+	})
+}
+
+// stubInactivityService 恢复被策略冻结的账户，返回固定的处置状态列表
+type stubInactivityService struct {
+	user.InactivityService
+	frozen map[uint]bool
+	logins []string
+	states []*models.UserInactivity
+	page   []int
+	err    error
+}
+
+func (s *stubInactivityService) ListStates(_ context.Context, page, pageSize int) ([]*models.UserInactivity, int64, error) {
+	s.page = []int{page, pageSize}
+	return s.states, int64(len(s.states)), s.err
+}
+
+func (s *stubInactivityService) RecordLogin(_ context.Context, account *models.User, ip string) (bool, error) {
+	if s.err != nil {
+		return false, s.err
+	}
+	s.logins = append(s.logins, ip)
+	if !s.frozen[account.ID] {
+		return false, nil
+	}
+	delete(s.frozen, account.ID)
+	account.Status = "active"
+	return true, nil
+}
+
+func (s *stubInactivityService) IsFrozen(_ context.Context, account *models.User) (bool, error) {
+	return account.Status == "inactive" && s.frozen[account.ID], nil
+}
+
+func TestUserLoginHandler_InactivityRestore(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	login := func(handler *UserLoginHandler) utils.Response {
+		reqBody, _ := json.Marshal(LoginRequest{Identifier: "test@example.com", Password: "testPassword123!"})
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/api/v1/auth/login", bytes.NewBuffer(reqBody))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Request.RemoteAddr = "203.0.113.7:1234"
+		handler.Login(c)
+		return decodeShareResponse(t, w)
+	}
+	frozenUser := func() *models.User {
+		account := setupTestUser()
+		account.Status = "inactive"
+		return account
+	}
+
+	t.Run("冻结的账户登录后恢复", func(t *testing.T) {
+		mockUserService := &MockLoginUserService{}
+		handler := setupTestLoginHandler(mockUserService)
+		handler.SetRefreshTokenStore(cache.NewMemoryRefreshTokenStore())
+		inactivity := &stubInactivityService{frozen: map[uint]bool{1: true}}
+		handler.SetInactivityService(inactivity)
+		mockUserService.On("GetUserByEmail", mock.Anything, "test@example.com").Return(frozenUser(), nil)
+
+		resp := login(handler)
+		assert.Equal(t, utils.CodeSuccess, resp.Code)
+		assert.Equal(t, []string{"203.0.113.7"}, inactivity.logins)
+	})
+
+	t.Run("管理员禁用的账户不能登录", func(t *testing.T) {
+		mockUserService := &MockLoginUserService{}
+		handler := setupTestLoginHandler(mockUserService)
+		handler.SetInactivityService(&stubInactivityService{})
+		mockUserService.On("GetUserByEmail", mock.Anything, "test@example.com").Return(frozenUser(), nil)
+
+		resp := login(handler)
+		assert.Equal(t, utils.CodeUnauthorized, resp.Code)
+	})
+
+	t.Run("两步验证用户只提交密码时保持冻结", func(t *testing.T) {
+		mockUserService := &MockLoginUserService{}
+		handler := setupTestLoginHandler(mockUserService)
+		handler.SetLoginChallengeStore(cache.NewMemoryLoginChallengeStore())
+		handler.SetTwoFactorService(&stubTwoFactorService{enabled: true, code: "123456"})
+		inactivity := &stubInactivityService{frozen: map[uint]bool{1: true}}
+		handler.SetInactivityService(inactivity)
+		mockUserService.On("GetUserByEmail", mock.Anything, "test@example.com").Return(frozenUser(), nil)
+
+		resp := login(handler)
+		assert.Equal(t, utils.CodeTwoFactorRequired, resp.Code)
+		assert.Empty(t, inactivity.logins, "未完成两步验证不记录登录")
+		assert.True(t, inactivity.frozen[1])
+	})
+
+	t.Run("两步验证通过后解除冻结", func(t *testing.T) {
+		mockUserService := &MockLoginUserService{}
+		handler := setupTestLoginHandler(mockUserService)
+		handler.SetRefreshTokenStore(cache.NewMemoryRefreshTokenStore())
+		handler.SetLoginChallengeStore(cache.NewMemoryLoginChallengeStore())
+		handler.SetTwoFactorService(&stubTwoFactorService{enabled: true, code: "123456"})
+		inactivity := &stubInactivityService{frozen: map[uint]bool{1: true}}
+		handler.SetInactivityService(inactivity)
+		mockUserService.On("GetUserByEmail", mock.Anything, "test@example.com").Return(frozenUser(), nil)
+		mockUserService.On("GetUserByID", mock.Anything, uint(1)).Return(frozenUser(), nil)
+
+		resp := login(handler)
+		require.Equal(t, utils.CodeTwoFactorRequired, resp.Code)
+		token := resp.Data.(map[string]interface{})["challenge_token"].(string)
+
+		reqBody, _ := json.Marshal(TwoFactorLoginRequest{ChallengeToken: token, Code: "123456"})
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/api/v1/auth/2fa", bytes.NewBuffer(reqBody))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Request.RemoteAddr = "203.0.113.7:1234"
+		handler.VerifyTwoFactor(c)
+
+		assert.Equal(t, utils.CodeSuccess, decodeShareResponse(t, w).Code)
+		assert.Equal(t, []string{"203.0.113.7"}, inactivity.logins)
+		assert.False(t, inactivity.frozen[1])
+	})
+
+	t.Run("刷新令牌不解除冻结", func(t *testing.T) {
+		mockUserService := &MockLoginUserService{}
+		handler := setupTestLoginHandler(mockUserService)
+		inactivity := &stubInactivityService{frozen: map[uint]bool{1: true}}
+		handler.SetInactivityService(inactivity)
+		account := frozenUser()
+		mockUserService.On("GetUserByID", mock.Anything, uint(1)).Return(account, nil)
+		refreshToken, err := handler.jwtManager.GenerateRefreshToken(uint64(account.ID), account.Username, account.Email, "user")
+		require.NoError(t, err)
+
+		reqBody, _ := json.Marshal(RefreshTokenRequest{RefreshToken: refreshToken})
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/api/v1/refresh", bytes.NewBuffer(reqBody))
+		c.Request.Header.Set("Content-Type", "application/json")
+		handler.RefreshToken(c)
+
+		resp := decodeShareResponse(t, w)
+		assert.Equal(t, utils.CodeUnauthorized, resp.Code)
+		assert.Contains(t, resp.Message, "禁用")
+		assert.Empty(t, inactivity.logins)
+		assert.True(t, inactivity.frozen[1])
+	})
+
+	t.Run("记录失败不影响登录", func(t *testing.T) {
+		mockUserService := &MockLoginUserService{}
+		handler := setupTestLoginHandler(mockUserService)
+		handler.SetRefreshTokenStore(cache.NewMemoryRefreshTokenStore())
+		handler.SetInactivityService(&stubInactivityService{err: fmt.Errorf("database unavailable")})
+		mockUserService.On("GetUserByEmail", mock.Anything, "test@example.com").Return(setupTestUser(), nil)
+
+		resp := login(handler)
+		assert.Equal(t, utils.CodeSuccess, resp.Code)
+	})
+}
+
+func TestUserLoginHandler_Sessions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockUserService := &MockLoginUserService{}
+	handler := setupTestLoginHandler(mockUserService)
+	testUser := setupTestUser()
+	mockUserService.On("GetUserByID", mock.Anything, uint(testUser.ID)).Return(testUser, nil)
+	refreshStore := cache.NewMemoryRefreshTokenStore()
+	sessionStore := cache.NewMemorySessionStore()
+	handler.SetRefreshTokenStore(refreshStore)
+	handler.SetSessionStore(sessionStore)
+
+	// 登录创建会话
+	login := func() string {
+		token, err := handler.jwtManager.GenerateRefreshToken(
+			uint64(testUser.ID), testUser.Username, testUser.Email, "user")
+		require.NoError(t, err)
+		require.NoError(t, handler.issueRefreshToken(context.Background(), token))
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("POST", "/api/v1/auth/login", nil)
+		c.Request.Header.Set("User-Agent", "cloudpan-desktop/1.0")
+		handler.recordSession(c, "", token)
+		return token
+	}
+	listSessions := func() []SessionInfo {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("GET", "/api/v1/auth/sessions", nil)
+		c.Set("user_id", uint64(testUser.ID))
+		handler.ListSessions(c)
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Data []SessionInfo `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.Data
+	}
+	revokeSession := func(userID uint64, sessionID string) utils.ResponseCode {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("DELETE", "/api/v1/auth/sessions/"+sessionID, nil)
+		c.Params = gin.Params{{Key: "id", Value: sessionID}}
+		c.Set("user_id", userID)
+		handler.RevokeSession(c)
+		return decodeShareResponse(t, w).Code
+	}
+
+	first := login()
+	second := login()
+	sessions := listSessions()
+	require.Len(t, sessions, 2)
+	assert.Equal(t, "cloudpan-desktop/1.0", sessions[0].Device)
+
+	// 刷新令牌沿用原会话
+	w := postRefreshToken(handler, first)
+	require.Equal(t, http.StatusOK, w.Code)
+	var refreshed struct {
+		Data LoginResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &refreshed))
+	sessions = listSessions()
+	require.Len(t, sessions, 2)
+	current, err := handler.jwtManager.ValidateToken(refreshed.Data.RefreshToken)
+	require.NoError(t, err)
+	session, err := cache.FindSessionByRefreshToken(context.Background(), sessionStore, uint64(testUser.ID), current.ID)
+	require.NoError(t, err)
+	assert.Equal(t, sessions[0].ID, session.ID, "最近刷新的会话排在最前")
+
+	// 不能结束其他用户的会话
+	assert.Equal(t, utils.CodeNotFound, revokeSession(uint64(testUser.ID)+1, session.ID))
+	assert.Equal(t, utils.CodeSuccess, revokeSession(uint64(testUser.ID), session.ID))
+	assert.Equal(t, http.StatusUnauthorized, postRefreshToken(handler, refreshed.Data.RefreshToken).Code)
+	require.Len(t, listSessions(), 1)
+
+	// 刷新令牌全部吊销(如修改密码)后会话不再返回
+	require.NoError(t, refreshStore.RevokeAllForUser(context.Background(), uint64(testUser.ID)))
+	assert.Empty(t, listSessions())
+	assert.Equal(t, http.StatusUnauthorized, postRefreshToken(handler, second).Code)
+}
+
+// postRefreshToken 调用刷新令牌接口
+func postRefreshToken(handler *UserLoginHandler, refreshToken string) *httptest.ResponseRecorder {
+	reqBody, _ := json.Marshal(RefreshTokenRequest{RefreshToken: refreshToken})
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("POST", "/api/v1/refresh", bytes.NewBuffer(reqBody))
+	c.Request.Header.Set("Content-Type", "application/json")
+	handler.RefreshToken(c)
+	return w
+}
+
+func TestUserLoginHandler_TwoFactor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	post := func(handle gin.HandlerFunc, body interface{}) (*httptest.ResponseRecorder, utils.Response) {
+		reqBody, _ := json.Marshal(body)
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/api/v1/auth/login", bytes.NewBuffer(reqBody))
+		c.Request.Header.Set("Content-Type", "application/json")
+		handle(c)
+		return w, decodeShareResponse(t, w)
+	}
+	login := LoginRequest{Identifier: "test@example.com", Password: "testPassword123!", RememberMe: true}
+	setup := func() (*UserLoginHandler, *MockLoginUserService, *stubTwoFactorService) {
+		mockUserService := &MockLoginUserService{}
+		handler := setupTestLoginHandler(mockUserService)
+		handler.SetRefreshTokenStore(cache.NewMemoryRefreshTokenStore())
+		handler.SetLoginChallengeStore(cache.NewMemoryLoginChallengeStore())
+		twoFactor := &stubTwoFactorService{enabled: true, code: "123456"}
+		handler.SetTwoFactorService(twoFactor)
+		mockUserService.On("GetUserByEmail", mock.Anything, "test@example.com").Return(setupTestUser(), nil)
+		mockUserService.On("GetUserByID", mock.Anything, uint(1)).Return(setupTestUser(), nil)
+		return handler, mockUserService, twoFactor
+	}
+	challengeToken := func(t *testing.T, handler *UserLoginHandler) string {
+		w, resp := post(handler.Login, login)
+		require.Equal(t, http.StatusUnauthorized, w.Code)
+		require.Equal(t, utils.CodeTwoFactorRequired, resp.Code)
+		data, ok := resp.Data.(map[string]interface{})
+		require.True(t, ok)
+		assert.NotEmpty(t, data["expires_at"])
+		assert.Nil(t, data["access_token"])
+		return data["challenge_token"].(string)
+	}
+
+	t.Run("未启用两步验证直接登录", func(t *testing.T) {
+		handler, _, twoFactor := setup()
+		twoFactor.enabled = false
+
+		w, resp := post(handler.Login, login)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, utils.CodeSuccess, resp.Code)
+	})
+
+	t.Run("提交验证码后签发令牌", func(t *testing.T) {
+		handler, _, twoFactor := setup()
+		token := challengeToken(t, handler)
+
+		_, resp := post(handler.VerifyTwoFactor, TwoFactorLoginRequest{ChallengeToken: token, Code: "000000"})
+		assert.Equal(t, utils.CodeUnauthorized, resp.Code)
+
+		w, resp := post(handler.VerifyTwoFactor, TwoFactorLoginRequest{ChallengeToken: token, Code: "123456"})
+		require.Equal(t, http.StatusOK, w.Code)
+		data, ok := resp.Data.(map[string]interface{})
+		require.True(t, ok)
+		assert.NotEmpty(t, data["access_token"])
+		assert.Equal(t, 1, twoFactor.verified)
+
+		// 挑战只能使用一次
+		_, resp = post(handler.VerifyTwoFactor, TwoFactorLoginRequest{ChallengeToken: token, Code: "123456"})
+		assert.Equal(t, utils.CodeUnauthorized, resp.Code)
+		assert.Contains(t, resp.Message, "重新登录")
+	})
+
+	t.Run("错误次数过多后挑战失效", func(t *testing.T) {
+		handler, _, _ := setup()
+		token := challengeToken(t, handler)
+
+		for i := 1; i < loginChallengeMaxAttempts; i++ {
+			_, resp := post(handler.VerifyTwoFactor, TwoFactorLoginRequest{ChallengeToken: token, Code: "000000"})
+			assert.Equal(t, utils.CodeUnauthorized, resp.Code)
+		}
+		w, resp := post(handler.VerifyTwoFactor, TwoFactorLoginRequest{ChallengeToken: token, Code: "000000"})
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, utils.CodeTooManyRequests, resp.Code)
+
+		_, resp = post(handler.VerifyTwoFactor, TwoFactorLoginRequest{ChallengeToken: token, Code: "123456"})
+		assert.Equal(t, utils.CodeUnauthorized, resp.Code)
+	})
+
+	t.Run("验证期间账户被禁用", func(t *testing.T) {
+		handler, mockUserService, _ := setup()
+		token := challengeToken(t, handler)
+		disabled := setupTestUser()
+		disabled.Status = "inactive"
+		mockUserService.ExpectedCalls = nil
+		mockUserService.On("GetUserByID", mock.Anything, uint(1)).Return(disabled, nil)
+
+		_, resp := post(handler.VerifyTwoFactor, TwoFactorLoginRequest{ChallengeToken: token, Code: "123456"})
+		assert.Equal(t, utils.CodeUnauthorized, resp.Code)
+		assert.Contains(t, resp.Message, "禁用")
+	})
 }
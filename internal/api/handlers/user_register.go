@@ -8,10 +8,13 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"cloudpan/internal/pkg/database"
 	"cloudpan/internal/pkg/email"
+	"cloudpan/internal/pkg/i18n"
 	"cloudpan/internal/pkg/utils"
 	"cloudpan/internal/repository/models"
 	"cloudpan/internal/service/user"
+	"cloudpan/internal/service/verification"
 )
 
 // CacheInterface 缓存接口，用于支持Mock测试
@@ -30,6 +33,7 @@ type RegisterRequest struct {
 	VerificationCode string `json:"verification_code" binding:"required,len=6" validate:"required,len=6"`        // 邮箱验证码
 	DisplayName      string `json:"display_name,omitempty" validate:"omitempty,min=1,max=100"`                   // 显示名称（可选）
 	AcceptTerms      bool   `json:"accept_terms" binding:"required" validate:"required"`                         // 接受服务条款
+	Language         string `json:"language,omitempty"`                                                          // 界面和邮件语言（可选），为空时按Accept-Language
 }
 
 // RegisterResponse 用户注册响应结构体
@@ -46,8 +50,9 @@ type RegisterResponse struct {
 
 // SendVerificationCodeRequest 发送验证码请求结构体
 type SendVerificationCodeRequest struct {
-	Email string `json:"email" binding:"required,email" validate:"required,email"`                  // 邮箱地址
-	Type  string `json:"type" binding:"required" validate:"required,oneof=register password_reset"` // 验证码类型
+	Email    string `json:"email" binding:"required,email" validate:"required,email"`                  // 邮箱地址
+	Type     string `json:"type" binding:"required" validate:"required,oneof=register password_reset"` // 验证码类型
+	Language string `json:"language,omitempty"`                                                        // 邮件语言（可选），为空时按Accept-Language
 }
 
 // SendVerificationCodeResponse 发送验证码响应结构体
@@ -62,6 +67,8 @@ type UserRegisterHandler struct {
 	userService  user.UserService
 	emailService email.EmailService
 	cacheManager CacheInterface
+	codes        verification.VerificationService
+	txManager    database.TxManager
 }
 
 // NewUserRegisterHandler 创建用户注册处理器
@@ -73,6 +80,21 @@ func NewUserRegisterHandler(userService user.UserService, emailService email.Ema
 	}
 }
 
+// SetVerificationService 设置验证码服务
+//
+// 设置后验证码以哈希保存在数据库中，缓存只作加速，Redis故障时仍可发送和校验验证码；
+// 未设置时验证码只保存在缓存中
+func (h *UserRegisterHandler) SetVerificationService(service verification.VerificationService) {
+	h.codes = service
+}
+
+// SetTxManager 设置事务管理器
+//
+// 设置后创建用户和将数据库中的验证码标记为已使用在同一事务中完成，同一验证码并发注册时只有一个成功
+func (h *UserRegisterHandler) SetTxManager(txManager database.TxManager) {
+	h.txManager = txManager
+}
+
 // createUserFromRequest 从请求创建用户对象
 func (h *UserRegisterHandler) createUserFromRequest(req *RegisterRequest) (*models.User, error) {
 	// 密码加密
@@ -100,9 +122,11 @@ func (h *UserRegisterHandler) createUserFromRequest(req *RegisterRequest) (*mode
 }
 
 // sendWelcomeEmailAsync 异步发送欢迎邮件
-func (h *UserRegisterHandler) sendWelcomeEmailAsync(email, username string) {
+//
+// 保留请求上下文中的追踪链路，但不随请求结束而取消
+func (h *UserRegisterHandler) sendWelcomeEmailAsync(ctx context.Context, email, username string) {
 	go func() {
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
 		defer cancel()
 		if err := h.emailService.SendWelcomeEmail(ctx, email, username); err != nil {
 			// 记录邮件发送失败，但不影响注册成功
@@ -133,7 +157,7 @@ func (h *UserRegisterHandler) buildRegisterResponse(user *models.User) RegisterR
 
 // Register 用户注册接口
 // @Summary 用户注册
-// @Description 新用户通过邮箱验证注册账号
+// @Description 新用户通过邮箱验证注册账号。请求体中的language或Accept-Language保存为用户的界面语言偏好，欢迎邮件及之后的验证码、重置密码邮件按该语言发送，不支持的语言使用默认语言
 // @Tags 用户认证
 // @Accept json
 // @Produce json
@@ -158,7 +182,8 @@ func (h *UserRegisterHandler) Register(c *gin.Context) {
 	}
 
 	// 验证邮箱验证码
-	if err := h.verifyEmailCode(c.Request.Context(), req.Email, req.VerificationCode, "register"); err != nil {
+	codeID, err := h.verifyEmailCode(c.Request.Context(), req.Email, req.VerificationCode, "register")
+	if err != nil {
 		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "邮箱验证码错误或已过期: "+err.Error())
 		return
 	}
@@ -181,23 +206,60 @@ func (h *UserRegisterHandler) Register(c *gin.Context) {
 		return
 	}
 
-	// 保存用户
-	if err := h.userService.CreateUser(c.Request.Context(), user); err != nil {
-		utils.ErrorWithMessage(c, utils.CodeInternalError, "创建用户失败: "+err.Error())
-		return
+	// 保存用户，设置事务管理器且使用验证码服务时同时将验证码标记为已使用
+	if h.txManager != nil && h.codes != nil {
+		var codeErr error
+		err = h.txManager.WithTransaction(c.Request.Context(), func(ctx context.Context) error {
+			if err := h.userService.CreateUser(ctx, user); err != nil {
+				return err
+			}
+			codeErr = h.codes.MarkCodeAsUsed(ctx, codeID)
+			return codeErr
+		})
+		if codeErr != nil {
+			utils.ErrorWithMessage(c, utils.CodeUnauthorized, "邮箱验证码错误或已过期: "+codeErr.Error())
+			return
+		}
+		if err != nil {
+			utils.ErrorWithMessage(c, utils.CodeInternalError, "创建用户失败: "+err.Error())
+			return
+		}
+	} else {
+		if err := h.userService.CreateUser(c.Request.Context(), user); err != nil {
+			utils.ErrorWithMessage(c, utils.CodeInternalError, "创建用户失败: "+err.Error())
+			return
+		}
+
+		// 清除验证码
+		h.clearEmailCode(c.Request.Context(), req.Email, "register", codeID)
 	}
 
-	// 清除验证码
-	h.clearEmailCode(c.Request.Context(), req.Email, "register")
+	// 保存注册时的语言偏好，之后的验证码、重置密码等邮件按该语言发送
+	ctx := c.Request.Context()
+	if language := requestLanguage(c, req.Language); language != "" {
+		if err := h.userService.SetUserPreference(ctx, user.ID, models.PreferenceCategoryUI, models.PreferenceKeyLanguage, language); err != nil {
+			// 语言偏好保存失败不影响注册，邮件使用默认语言
+			_ = err // 明确忽略错误
+		}
+		ctx = email.WithLanguage(ctx, language)
+	}
 
 	// 发送欢迎邮件
-	h.sendWelcomeEmailAsync(user.Email, user.Username)
+	h.sendWelcomeEmailAsync(ctx, user.Email, user.Username)
 
 	// 返回响应
 	response := h.buildRegisterResponse(user)
 	utils.Created(c, response)
 }
 
+// requestLanguage 返回请求的语言：依次尝试请求体中的language和Accept-Language，取第一个受支持的语言
+//
+// 基础语言相同也视为支持(如 en-GB 返回 en-US)，都不支持时返回空字符串
+func requestLanguage(c *gin.Context, explicit string) string {
+	preferred := append([]string{explicit}, i18n.ParseAcceptLanguage(c.GetHeader("Accept-Language"))...)
+	return i18n.Match(preferred, i18n.SupportedLanguages)
+}
+
 // validateSendCodeRequest 验证发送验证码请求
 func (h *UserRegisterHandler) validateSendCodeRequest(req *SendVerificationCodeRequest) error {
 	// 验证邮箱格式
@@ -247,7 +309,7 @@ func (h *UserRegisterHandler) generateAndStoreCode(email, codeType string) (stri
 
 // SendVerificationCode 发送邮箱验证码
 // @Summary 发送邮箱验证码
-// @Description 为注册或密码重置发送邮箱验证码
+// @Description 为注册或密码重置发送邮箱验证码，邮件语言依次按请求体中的language、Accept-Language选择，都不支持时使用默认语言
 // @Tags 用户认证
 // @Accept json
 // @Produce json
@@ -282,17 +344,35 @@ func (h *UserRegisterHandler) SendVerificationCode(c *gin.Context) {
 		return
 	}
 
-	// 生成并存储验证码
-	code, expiresIn, err := h.generateAndStoreCode(req.Email, req.Type)
-	if err != nil {
-		utils.ErrorWithMessage(c, utils.CodeInternalError, err.Error())
-		return
+	// 按请求语言发送验证码邮件
+	ctx := c.Request.Context()
+	if language := requestLanguage(c, req.Language); language != "" {
+		ctx = email.WithLanguage(ctx, language)
 	}
 
-	// 发送验证码邮件
-	if err := h.emailService.SendVerificationCode(c.Request.Context(), req.Email, code); err != nil {
-		utils.ErrorWithMessage(c, utils.CodeInternalError, "发送验证码失败: "+err.Error())
-		return
+	var expiresIn time.Duration
+	if h.codes != nil {
+		// 验证码服务保存验证码并发送邮件
+		record, err := h.codes.GenerateEmailCode(ctx, req.Email, req.Type, nil, c.ClientIP())
+		if err != nil {
+			respondServiceError(c, err, "发送验证码失败")
+			return
+		}
+		expiresIn = time.Until(record.ExpiresAt).Round(time.Second)
+	} else {
+		// 生成并存储验证码
+		code, ttl, err := h.generateAndStoreCode(req.Email, req.Type)
+		if err != nil {
+			utils.ErrorWithMessage(c, utils.CodeInternalError, err.Error())
+			return
+		}
+		expiresIn = ttl
+
+		// 发送验证码邮件
+		if err := h.emailService.SendVerificationCode(ctx, req.Email, code); err != nil {
+			utils.ErrorWithMessage(c, utils.CodeInternalError, "发送验证码失败: "+err.Error())
+			return
+		}
 	}
 
 	// 记录发送时间（用于频率限制）
@@ -351,25 +431,41 @@ func (h *UserRegisterHandler) hashPassword(password string) (string, error) {
 	return utils.HashPassword(password)
 }
 
-// verifyEmailCode 验证邮箱验证码
-func (h *UserRegisterHandler) verifyEmailCode(_ context.Context, email, code, codeType string) error {
+// verifyEmailCode 验证邮箱验证码，返回数据库中的验证码ID，未设置验证码服务时为0
+func (h *UserRegisterHandler) verifyEmailCode(ctx context.Context, email, code, codeType string) (uint, error) {
+	if h.codes != nil {
+		record, err := h.codes.VerifyEmailCode(ctx, strings.ToLower(strings.TrimSpace(email)), codeType, code)
+		if err != nil {
+			return 0, err
+		}
+		return record.ID, nil
+	}
+
 	cacheKey := fmt.Sprintf("email_code:%s:%s", codeType, email)
 
 	var storedCode string
 	err := h.cacheManager.Get(cacheKey, &storedCode)
 	if err != nil {
-		return fmt.Errorf("验证码已过期或不存在")
+		return 0, fmt.Errorf("验证码已过期或不存在")
 	}
 
 	if storedCode != code {
-		return fmt.Errorf("验证码不正确")
+		return 0, fmt.Errorf("验证码不正确")
 	}
 
-	return nil
+	return 0, nil
 }
 
-// clearEmailCode 清除邮箱验证码
-func (h *UserRegisterHandler) clearEmailCode(_ context.Context, email, codeType string) {
+// clearEmailCode 清除邮箱验证码，设置验证码服务时将数据库中的验证码标记为已使用
+func (h *UserRegisterHandler) clearEmailCode(ctx context.Context, email, codeType string, codeID uint) {
+	if h.codes != nil {
+		if err := h.codes.MarkCodeAsUsed(ctx, codeID); err != nil {
+			// 验证码已在注册前校验，标记失败时验证码仍会按时过期
+			_ = err // 明确忽略错误
+		}
+		return
+	}
+
 	cacheKey := fmt.Sprintf("email_code:%s:%s", codeType, email)
 	if err := h.cacheManager.Delete(cacheKey); err != nil {
 		// 缓存删除失败，记录错误但不影响主流程
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -13,8 +14,12 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 
 	"cloudpan/internal/pkg/email"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/repository/models"
+	"cloudpan/internal/service/verification"
 )
 
 // Mock对象
@@ -730,3 +735,168 @@ func TestRegisterHandler_EdgeCases(t *testing.T) {
 		assert.Equal(t, http.StatusCreated, w.Code)
 	})
 }
+
+// stubCodeService 内存中的验证码服务，模拟验证码保存在数据库中
+type stubCodeService struct {
+	verification.VerificationService
+	code     string
+	usedID   uint
+	usedErr  error
+	language string
+}
+
+func (s *stubCodeService) GenerateEmailCode(ctx context.Context, target, codeType string, _ *uint, _ string) (*models.VerificationCode, error) {
+	s.code = "654321"
+	s.language = email.LanguageFromContext(ctx)
+	record := &models.VerificationCode{Target: target, Type: codeType, ExpiresAt: time.Now().Add(15 * time.Minute)}
+	record.ID = 11
+	return record, nil
+}
+
+func (s *stubCodeService) VerifyEmailCode(_ context.Context, target, codeType, code string) (*models.VerificationCode, error) {
+	if code != s.code {
+		return nil, pkgErrors.NewValidationError("code", "验证码错误")
+	}
+	record := &models.VerificationCode{Target: target, Type: codeType}
+	record.ID = 11
+	return record, nil
+}
+
+func (s *stubCodeService) MarkCodeAsUsed(_ context.Context, codeID uint) error {
+	if s.usedErr != nil {
+		return s.usedErr
+	}
+	s.usedID = codeID
+	return nil
+}
+
+// TestRegisterHandler_RedisDown 测试Redis故障时使用数据库中的验证码完成注册
+func TestRegisterHandler_RedisDown(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	handler, userService, emailService, cacheManager := setupTestHandler()
+	codes := &stubCodeService{}
+	handler.SetVerificationService(codes)
+
+	redisDown := errors.New("redis: connection refused")
+	cacheManager.On("Get", mock.Anything, mock.Anything).Return(redisDown)
+	cacheManager.On("SetWithTTL", mock.Anything, mock.Anything, mock.Anything).Return(redisDown)
+	userService.On("CheckEmailExists", mock.Anything, "test@example.com").Return(false, nil)
+	userService.On("CheckUserExists", mock.Anything, "test@example.com", "testuser").Return(false, nil)
+	userService.On("CreateUser", mock.Anything, mock.AnythingOfType("*models.User")).Return(nil)
+	emailService.On("SendWelcomeEmail", mock.Anything, "test@example.com", "testuser").Return(nil)
+
+	router := gin.New()
+	router.POST("/send-code", handler.SendVerificationCode)
+	router.POST("/register", handler.Register)
+
+	req, err := createTestRequest(http.MethodPost, "/send-code", SendVerificationCodeRequest{Email: "test@example.com", Type: "register"})
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "654321", codes.code)
+
+	req, err = createTestRequest(http.MethodPost, "/register", RegisterRequest{
+		Email:            "test@example.com",
+		Username:         "testuser",
+		Password:         "Str0ng@Passw0rd123!",
+		ConfirmPassword:  "Str0ng@Passw0rd123!",
+		VerificationCode: "654321",
+		AcceptTerms:      true,
+	})
+	require.NoError(t, err)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, uint(11), codes.usedID)
+	emailService.AssertNotCalled(t, "SendVerificationCode", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// TestRegisterHandler_Language 测试按请求语言发送邮件并保存语言偏好
+func TestRegisterHandler_Language(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	handler, userService, emailService, cacheManager := setupTestHandler()
+	codes := &stubCodeService{}
+	handler.SetVerificationService(codes)
+
+	welcomeLanguage := make(chan string, 1)
+	cacheManager.On("Get", mock.Anything, mock.Anything).Return(errors.New("cache miss"))
+	cacheManager.On("SetWithTTL", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	userService.On("CheckEmailExists", mock.Anything, "test@example.com").Return(false, nil)
+	userService.On("CheckUserExists", mock.Anything, "test@example.com", "testuser").Return(false, nil)
+	userService.On("CreateUser", mock.Anything, mock.AnythingOfType("*models.User")).Return(nil).Run(func(args mock.Arguments) {
+		args.Get(1).(*models.User).ID = 5
+	})
+	userService.On("SetUserPreference", mock.Anything, uint(5), models.PreferenceCategoryUI, models.PreferenceKeyLanguage, "en-US").Return(nil)
+	emailService.On("SendWelcomeEmail", mock.Anything, "test@example.com", "testuser").Return(nil).Run(func(args mock.Arguments) {
+		welcomeLanguage <- email.LanguageFromContext(args.Get(0).(context.Context))
+	})
+
+	router := gin.New()
+	router.POST("/send-code", handler.SendVerificationCode)
+	router.POST("/register", handler.Register)
+
+	req, err := createTestRequest(http.MethodPost, "/send-code", SendVerificationCodeRequest{Email: "test@example.com", Type: "register"})
+	require.NoError(t, err)
+	req.Header.Set("Accept-Language", "fr-FR,en-GB;q=0.8")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "en-US", codes.language, "按Accept-Language匹配支持的语言")
+
+	req, err = createTestRequest(http.MethodPost, "/register", RegisterRequest{
+		Email:            "test@example.com",
+		Username:         "testuser",
+		Password:         "Str0ng@Passw0rd123!",
+		ConfirmPassword:  "Str0ng@Passw0rd123!",
+		VerificationCode: "654321",
+		AcceptTerms:      true,
+		Language:         "en",
+	})
+	require.NoError(t, err)
+	req.Header.Set("Accept-Language", "ja")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusCreated, w.Code)
+	userService.AssertExpectations(t)
+	select {
+	case language := <-welcomeLanguage:
+		assert.Equal(t, "en-US", language, "请求体中的language优先")
+	case <-time.After(time.Second):
+		t.Fatal("欢迎邮件未发送")
+	}
+}
+
+// TestRegisterHandler_CodeUsedInTransaction 测试验证码已被并发使用时回滚创建的用户
+func TestRegisterHandler_CodeUsedInTransaction(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	handler, userService, _, _ := setupTestHandler()
+	codes := &stubCodeService{code: "654321", usedErr: pkgErrors.NewValidationError("code", "验证码不存在或已使用")}
+	txManager := &recordingTxManager{}
+	handler.SetVerificationService(codes)
+	handler.SetTxManager(txManager)
+	userService.On("CheckUserExists", mock.Anything, "test@example.com", "testuser").Return(false, nil)
+	userService.On("CreateUser", mock.Anything, mock.AnythingOfType("*models.User")).Return(nil)
+
+	req, err := createTestRequest(http.MethodPost, "/register", RegisterRequest{
+		Email:            "test@example.com",
+		Username:         "testuser",
+		Password:         "Str0ng@Passw0rd123!",
+		ConfirmPassword:  "Str0ng@Passw0rd123!",
+		VerificationCode: "654321",
+		AcceptTerms:      true,
+	})
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	handler.Register(c)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, 1, txManager.calls)
+	assert.Error(t, txManager.err, "标记验证码失败时创建用户随事务回滚")
+	userService.AssertCalled(t, "CreateUser", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,297 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/cache"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+	"cloudpan/internal/service/user"
+)
+
+// twoFactorMethodSMS 两步验证使用短信验证码
+const twoFactorMethodSMS = "sms"
+
+// smsLoginSentMessage 发送短信登录验证码的响应消息，账户不存在时同样返回，避免探测账户
+const smsLoginSentMessage = "如果账户已绑定手机号，验证码已发送"
+
+// SMSLoginCodeRequest 发送短信登录验证码请求结构体
+type SMSLoginCodeRequest struct {
+	// 登录标识符（邮箱或用户名），验证码发送到账户已验证的手机号
+	Identifier string `json:"identifier" binding:"required" example:"user@example.com"`
+}
+
+// SMSLoginRequest 短信验证码登录请求结构体
+type SMSLoginRequest struct {
+	// 登录标识符（邮箱或用户名）
+	Identifier string `json:"identifier" binding:"required" example:"user@example.com"`
+	// 短信验证码
+	Code string `json:"code" binding:"required" example:"123456"`
+	// 记住我
+	RememberMe bool `json:"remember_me,omitempty" example:"false"`
+}
+
+// TwoFactorSMSRequest 发送两步验证短信请求结构体
+type TwoFactorSMSRequest struct {
+	// 登录响应中的挑战令牌
+	ChallengeToken string `json:"challenge_token" binding:"required" example:"3b5d5c3712955042212316173ccf37be"`
+}
+
+// TwoFactorSMSInfo 两步验证短信发送结果
+type TwoFactorSMSInfo struct {
+	// 接收验证码的手机号(脱敏)
+	Phone string `json:"phone" example:"8613****38000"`
+	// 验证码过期时间
+	ExpiresAt string `json:"expires_at" example:"2024-01-01T00:05:00Z"`
+}
+
+// SendLoginSMS 发送短信登录验证码
+//
+// @Summary 发送短信登录验证码
+// @Description 向账户已验证的手机号发送登录验证码，验证码5分钟内有效。账户不存在或未绑定手机号时同样返回成功，避免探测账户。
+// @Description 同一手机号每小时的发送条数有上限(third_party.sms.hourly_limit)
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param request body SMSLoginCodeRequest true "发送验证码请求"
+// @Success 200 {object} utils.Response "已受理"
+// @Failure 400 {object} utils.Response "请求参数错误或未启用短信验证码"
+// @Failure 429 {object} utils.Response "发送过于频繁"
+// @Failure 500 {object} utils.Response "短信发送失败"
+// @Router /api/v1/auth/sms/send [post]
+func (h *UserLoginHandler) SendLoginSMS(c *gin.Context) {
+	var req SMSLoginCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+		return
+	}
+	if h.codes == nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "短信验证码未启用")
+		return
+	}
+
+	ctx := c.Request.Context()
+	identifier := strings.TrimSpace(req.Identifier)
+	account, err := h.findUserByIdentifier(ctx, identifier, h.detectLoginType(identifier))
+	phone, ok := verifiedPhone(account)
+	if err != nil || !ok || account.Status != "active" {
+		h.logger.Info("SMS login code not sent: account not eligible",
+			zap.String("identifier", identifier),
+			zap.String("ip", c.ClientIP()))
+		utils.SuccessWithMessage(c, smsLoginSentMessage, nil)
+		return
+	}
+
+	if _, err := h.codes.GeneratePhoneCode(ctx, phone, models.VerificationTypeLogin, &account.ID, c.ClientIP()); err != nil {
+		h.logger.Warn("Failed to send SMS login code",
+			zap.Uint("user_id", account.ID),
+			zap.Error(err),
+			zap.String("ip", c.ClientIP()))
+		respondPhoneCodeError(c, err)
+		return
+	}
+	utils.SuccessWithMessage(c, smsLoginSentMessage, nil)
+}
+
+// LoginWithSMS 短信验证码登录
+//
+// @Summary 短信验证码登录
+// @Description 使用发送到账户已验证手机号的验证码代替密码登录，之后的检查与密码登录相同：
+// @Description 已启用两步验证时返回挑战令牌(code=1027)，邮箱域名已开启强制单点登录时返回code=1029
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param request body SMSLoginRequest true "短信登录请求"
+// @Success 200 {object} utils.Response{data=LoginResponse} "登录成功"
+// @Failure 400 {object} utils.Response "请求参数错误或未启用短信验证码"
+// @Failure 401 {object} utils.Response{data=TwoFactorChallengeInfo} "验证码错误或已过期；已启用两步验证时返回挑战令牌(code=1027)"
+// @Failure 403 {object} utils.Response{data=PendingDeletionInfo} "账户已计划删除(code=1026)或需要单点登录(code=1029)"
+// @Failure 500 {object} utils.Response "内部服务器错误"
+// @Router /api/v1/auth/sms/login [post]
+func (h *UserLoginHandler) LoginWithSMS(c *gin.Context) {
+	var req SMSLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+		return
+	}
+	if h.codes == nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "短信验证码未启用")
+		return
+	}
+
+	ctx := c.Request.Context()
+	identifier := strings.TrimSpace(req.Identifier)
+	account, err := h.findUserByIdentifier(ctx, identifier, h.detectLoginType(identifier))
+	if err != nil {
+		h.recordLoginFailure(c, 0, "user_not_found", map[string]interface{}{"identifier": identifier, "method": "sms"})
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "验证码错误或已过期")
+		return
+	}
+	phone, ok := verifiedPhone(account)
+	if !ok {
+		h.recordLoginFailure(c, account.ID, "phone_not_verified", map[string]interface{}{"method": "sms"})
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "验证码错误或已过期")
+		return
+	}
+
+	valid, err := h.consumePhoneCode(ctx, phone, models.VerificationTypeLogin, req.Code)
+	if err != nil {
+		h.logger.Error("Failed to verify SMS login code", zap.Uint("user_id", account.ID), zap.Error(err))
+		utils.InternalErrorWithMessage(c, "登录失败")
+		return
+	}
+	if !valid {
+		h.logger.Warn("SMS login code verification failed",
+			zap.Uint("user_id", account.ID),
+			zap.String("ip", c.ClientIP()))
+		h.recordLoginFailure(c, account.ID, "invalid_sms_code", nil)
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "验证码错误或已过期")
+		return
+	}
+
+	if h.requireSSO(c, account) {
+		return
+	}
+	h.completeLogin(c, account, defaultRole, req.RememberMe)
+}
+
+// SendTwoFactorSMS 发送两步验证短信
+//
+// @Summary 发送两步验证短信
+// @Description 登录返回需要两步验证(code=1027)后，向账户已验证的手机号发送验证码，随后调用两步验证登录接口以method=sms提交。
+// @Description 未绑定已验证手机号时返回code=1014，需使用验证器App或备用码
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param request body TwoFactorSMSRequest true "发送两步验证短信请求"
+// @Success 200 {object} utils.Response{data=TwoFactorSMSInfo} "已发送"
+// @Failure 400 {object} utils.Response "请求参数错误、未启用短信验证码或未绑定已验证手机号(code=1014)"
+// @Failure 401 {object} utils.Response "挑战已过期"
+// @Failure 429 {object} utils.Response "发送过于频繁"
+// @Failure 500 {object} utils.Response "短信发送失败"
+// @Router /api/v1/auth/2fa/sms [post]
+func (h *UserLoginHandler) SendTwoFactorSMS(c *gin.Context) {
+	var req TwoFactorSMSRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+		return
+	}
+	if h.codes == nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "短信验证码未启用")
+		return
+	}
+	store := h.loginChallengeStore()
+	if h.twoFactor == nil || store == nil {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "验证已过期，请重新登录")
+		return
+	}
+
+	ctx := c.Request.Context()
+	challenge, err := store.Get(ctx, req.ChallengeToken)
+	if err != nil {
+		if !errors.Is(err, cache.ErrLoginChallengeNotFound) {
+			h.logger.Error("Failed to get login challenge", zap.Error(err))
+			utils.InternalErrorWithMessage(c, "发送验证码失败")
+			return
+		}
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "验证已过期，请重新登录")
+		return
+	}
+	account, err := h.userService.GetUserByID(ctx, uint(challenge.UserID))
+	if err != nil {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户不存在")
+		return
+	}
+	phone, ok := verifiedPhone(account)
+	if !ok {
+		utils.ErrorWithMessage(c, utils.CodePhoneNotVerified, "未绑定已验证的手机号，请使用验证器App或备用码")
+		return
+	}
+
+	record, err := h.codes.GeneratePhoneCode(ctx, phone, models.VerificationTypeMFA, &account.ID, c.ClientIP())
+	if err != nil {
+		h.logger.Warn("Failed to send two-factor SMS",
+			zap.Uint("user_id", account.ID),
+			zap.Error(err),
+			zap.String("ip", c.ClientIP()))
+		respondPhoneCodeError(c, err)
+		return
+	}
+	utils.SuccessWithMessage(c, "验证码已发送", TwoFactorSMSInfo{
+		Phone:     utils.MaskPhone(phone),
+		ExpiresAt: record.ExpiresAt.Format(time.RFC3339),
+	})
+}
+
+// verifySecondFactor 按方式核对两步验证码，验证码错误时返回 user.ErrInvalidTwoFactorCode
+func (h *UserLoginHandler) verifySecondFactor(ctx context.Context, userID uint, method, code string) error {
+	if method != twoFactorMethodSMS {
+		return h.twoFactor.Verify(ctx, userID, code)
+	}
+	if h.codes == nil {
+		return user.ErrInvalidTwoFactorCode
+	}
+	account, err := h.userService.GetUserByID(ctx, userID)
+	if err != nil {
+		return user.ErrInvalidTwoFactorCode
+	}
+	phone, ok := verifiedPhone(account)
+	if !ok {
+		return user.ErrInvalidTwoFactorCode
+	}
+	valid, err := h.consumePhoneCode(ctx, phone, models.VerificationTypeMFA, code)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return user.ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+// consumePhoneCode 核对短信验证码并标记为已使用，验证码错误、过期或已被并发请求使用时返回false
+func (h *UserLoginHandler) consumePhoneCode(ctx context.Context, phone, codeType, code string) (bool, error) {
+	var validationErr *pkgErrors.ValidationError
+	record, err := h.codes.VerifyPhoneCode(ctx, phone, codeType, code)
+	if err != nil {
+		if errors.As(err, &validationErr) {
+			return false, nil
+		}
+		return false, err
+	}
+	if err := h.codes.MarkCodeAsUsed(ctx, record.ID); err != nil {
+		if errors.As(err, &validationErr) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// respondPhoneCodeError 写入发送短信验证码失败的响应：频率限制返回429，号码无效等校验错误返回其消息
+func respondPhoneCodeError(c *gin.Context, err error) {
+	var validationErr *pkgErrors.ValidationError
+	switch {
+	case errors.As(err, &validationErr) && validationErr.Field == "rate_limit":
+		utils.ErrorWithMessage(c, utils.CodeTooManyRequests, validationErr.Message)
+	case errors.As(err, &validationErr):
+		utils.ErrorWithMessage(c, utils.CodeValidationError, validationErr.Message)
+	default:
+		utils.InternalErrorWithMessage(c, "发送验证码失败，请稍后再试")
+	}
+}
+
+// verifiedPhone 返回账户已验证的手机号，未绑定或未验证时返回false
+func verifiedPhone(account *models.User) (string, bool) {
+	if account == nil || account.Phone == nil || *account.Phone == "" || !account.PhoneVerified {
+		return "", false
+	}
+	return *account.Phone, true
+}
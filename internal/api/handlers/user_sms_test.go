@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"cloudpan/internal/pkg/cache"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+)
+
+// setupTestPhoneUser 返回已验证手机号的测试用户
+func setupTestPhoneUser() *models.User {
+	account := setupTestUser()
+	phone := "+8613800138000"
+	account.Phone = &phone
+	account.PhoneVerified = true
+	return account
+}
+
+func postJSON(t *testing.T, handle gin.HandlerFunc, body interface{}) (*httptest.ResponseRecorder, utils.Response) {
+	reqBody, _ := json.Marshal(body)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/auth/sms", bytes.NewBuffer(reqBody))
+	c.Request.Header.Set("Content-Type", "application/json")
+	handle(c)
+	return w, decodeShareResponse(t, w)
+}
+
+func TestUserLoginHandler_SMSLogin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	record := &models.VerificationCode{ExpiresAt: time.Now().Add(5 * time.Minute)}
+	record.ID = 9
+
+	setup := func(account *models.User) (*UserLoginHandler, *MockVerificationService) {
+		mockUserService := &MockLoginUserService{}
+		handler := setupTestLoginHandler(mockUserService)
+		handler.SetRefreshTokenStore(cache.NewMemoryRefreshTokenStore())
+		codes := &MockVerificationService{}
+		handler.SetVerificationService(codes)
+		mockUserService.On("GetUserByEmail", mock.Anything, "test@example.com").Return(account, nil)
+		mockUserService.On("GetUserByUsername", mock.Anything, "nobody").Return(nil, errors.New("record not found"))
+		return handler, codes
+	}
+
+	t.Run("发送验证码到已验证手机号", func(t *testing.T) {
+		handler, codes := setup(setupTestPhoneUser())
+		codes.On("GeneratePhoneCode", mock.Anything, "+8613800138000", models.VerificationTypeLogin, mock.Anything, mock.Anything).Return(record, nil).Once()
+
+		w, resp := postJSON(t, handler.SendLoginSMS, SMSLoginCodeRequest{Identifier: "test@example.com"})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, smsLoginSentMessage, resp.Message)
+
+		// 账户不存在时同样返回成功
+		w, resp = postJSON(t, handler.SendLoginSMS, SMSLoginCodeRequest{Identifier: "nobody"})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, smsLoginSentMessage, resp.Message)
+		codes.AssertExpectations(t)
+	})
+
+	t.Run("未验证手机号不发送", func(t *testing.T) {
+		account := setupTestPhoneUser()
+		account.PhoneVerified = false
+		handler, codes := setup(account)
+
+		w, _ := postJSON(t, handler.SendLoginSMS, SMSLoginCodeRequest{Identifier: "test@example.com"})
+		assert.Equal(t, http.StatusOK, w.Code)
+		codes.AssertNotCalled(t, "GeneratePhoneCode", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+		_, resp := postJSON(t, handler.LoginWithSMS, SMSLoginRequest{Identifier: "test@example.com", Code: "123456"})
+		assert.Equal(t, utils.CodeUnauthorized, resp.Code)
+		codes.AssertNotCalled(t, "VerifyPhoneCode", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("发送过于频繁", func(t *testing.T) {
+		handler, codes := setup(setupTestPhoneUser())
+		codes.On("GeneratePhoneCode", mock.Anything, "+8613800138000", models.VerificationTypeLogin, mock.Anything, mock.Anything).
+			Return(nil, pkgErrors.NewValidationError("rate_limit", "该手机号获取验证码过于频繁，每小时最多5条"))
+
+		w, resp := postJSON(t, handler.SendLoginSMS, SMSLoginCodeRequest{Identifier: "test@example.com"})
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Contains(t, resp.Message, "每小时最多5条")
+	})
+
+	t.Run("验证码正确后签发令牌", func(t *testing.T) {
+		handler, codes := setup(setupTestPhoneUser())
+		codes.On("VerifyPhoneCode", mock.Anything, "+8613800138000", models.VerificationTypeLogin, "000000").
+			Return(nil, pkgErrors.NewValidationError("code", "验证码错误"))
+		codes.On("VerifyPhoneCode", mock.Anything, "+8613800138000", models.VerificationTypeLogin, "123456").Return(record, nil)
+		codes.On("MarkCodeAsUsed", mock.Anything, uint(9)).Return(nil).Once()
+
+		w, resp := postJSON(t, handler.LoginWithSMS, SMSLoginRequest{Identifier: "test@example.com", Code: "000000"})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, utils.CodeUnauthorized, resp.Code)
+
+		w, resp = postJSON(t, handler.LoginWithSMS, SMSLoginRequest{Identifier: "test@example.com", Code: "123456"})
+		require.Equal(t, http.StatusOK, w.Code)
+		data, ok := resp.Data.(map[string]interface{})
+		require.True(t, ok)
+		assert.NotEmpty(t, data["access_token"])
+		codes.AssertExpectations(t)
+	})
+
+	t.Run("并发使用的验证码只能登录一次", func(t *testing.T) {
+		handler, codes := setup(setupTestPhoneUser())
+		codes.On("VerifyPhoneCode", mock.Anything, "+8613800138000", models.VerificationTypeLogin, "123456").Return(record, nil)
+		codes.On("MarkCodeAsUsed", mock.Anything, uint(9)).Return(pkgErrors.NewValidationError("code", "验证码不存在或已使用"))
+
+		_, resp := postJSON(t, handler.LoginWithSMS, SMSLoginRequest{Identifier: "test@example.com", Code: "123456"})
+		assert.Equal(t, utils.CodeUnauthorized, resp.Code)
+	})
+
+	t.Run("未启用短信验证码", func(t *testing.T) {
+		handler := setupTestLoginHandler(&MockLoginUserService{})
+		w, resp := postJSON(t, handler.LoginWithSMS, SMSLoginRequest{Identifier: "test@example.com", Code: "123456"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, resp.Message, "未启用")
+	})
+}
+
+func TestUserLoginHandler_TwoFactorSMS(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	record := &models.VerificationCode{ExpiresAt: time.Now().Add(5 * time.Minute)}
+	record.ID = 11
+
+	setup := func(account *models.User) (*UserLoginHandler, *MockVerificationService, *stubTwoFactorService, string) {
+		mockUserService := &MockLoginUserService{}
+		handler := setupTestLoginHandler(mockUserService)
+		handler.SetRefreshTokenStore(cache.NewMemoryRefreshTokenStore())
+		handler.SetLoginChallengeStore(cache.NewMemoryLoginChallengeStore())
+		twoFactor := &stubTwoFactorService{enabled: true, code: "654321"}
+		handler.SetTwoFactorService(twoFactor)
+		codes := &MockVerificationService{}
+		handler.SetVerificationService(codes)
+		mockUserService.On("GetUserByEmail", mock.Anything, "test@example.com").Return(account, nil)
+		mockUserService.On("GetUserByID", mock.Anything, uint(1)).Return(account, nil)
+
+		_, resp := postJSON(t, handler.Login, LoginRequest{Identifier: "test@example.com", Password: "testPassword123!"})
+		require.Equal(t, utils.CodeTwoFactorRequired, resp.Code)
+		token := resp.Data.(map[string]interface{})["challenge_token"].(string)
+		return handler, codes, twoFactor, token
+	}
+
+	t.Run("短信验证码完成两步验证", func(t *testing.T) {
+		handler, codes, twoFactor, token := setup(setupTestPhoneUser())
+		codes.On("GeneratePhoneCode", mock.Anything, "+8613800138000", models.VerificationTypeMFA, mock.Anything, mock.Anything).Return(record, nil).Once()
+		codes.On("VerifyPhoneCode", mock.Anything, "+8613800138000", models.VerificationTypeMFA, "000000").
+			Return(nil, pkgErrors.NewValidationError("code", "验证码错误"))
+		codes.On("VerifyPhoneCode", mock.Anything, "+8613800138000", models.VerificationTypeMFA, "123456").Return(record, nil)
+		codes.On("MarkCodeAsUsed", mock.Anything, uint(11)).Return(nil).Once()
+
+		w, resp := postJSON(t, handler.SendTwoFactorSMS, TwoFactorSMSRequest{ChallengeToken: token})
+		require.Equal(t, http.StatusOK, w.Code)
+		data := resp.Data.(map[string]interface{})
+		assert.Equal(t, utils.MaskPhone("+8613800138000"), data["phone"])
+		assert.NotContains(t, data["phone"], "0013", "返回脱敏的手机号")
+		assert.NotEmpty(t, data["expires_at"])
+
+		// 短信验证码错误同样计入挑战的错误次数
+		_, resp = postJSON(t, handler.VerifyTwoFactor, TwoFactorLoginRequest{ChallengeToken: token, Code: "000000", Method: "sms"})
+		assert.Equal(t, utils.CodeUnauthorized, resp.Code)
+
+		w, resp = postJSON(t, handler.VerifyTwoFactor, TwoFactorLoginRequest{ChallengeToken: token, Code: "123456", Method: "sms"})
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.NotEmpty(t, resp.Data.(map[string]interface{})["access_token"])
+		assert.Zero(t, twoFactor.verified, "短信方式不调用TOTP校验")
+		codes.AssertExpectations(t)
+	})
+
+	t.Run("未验证手机号只能使用验证器", func(t *testing.T) {
+		account := setupTestPhoneUser()
+		account.PhoneVerified = false
+		handler, codes, _, token := setup(account)
+
+		w, resp := postJSON(t, handler.SendTwoFactorSMS, TwoFactorSMSRequest{ChallengeToken: token})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, utils.CodePhoneNotVerified, resp.Code)
+
+		_, resp = postJSON(t, handler.VerifyTwoFactor, TwoFactorLoginRequest{ChallengeToken: token, Code: "123456", Method: "sms"})
+		assert.Equal(t, utils.CodeUnauthorized, resp.Code)
+		codes.AssertNotCalled(t, "VerifyPhoneCode", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+		w, _ = postJSON(t, handler.VerifyTwoFactor, TwoFactorLoginRequest{ChallengeToken: token, Code: "654321"})
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("挑战已过期", func(t *testing.T) {
+		handler, _, _, _ := setup(setupTestPhoneUser())
+		_, resp := postJSON(t, handler.SendTwoFactorSMS, TwoFactorSMSRequest{ChallengeToken: "missing"})
+		assert.Equal(t, utils.CodeUnauthorized, resp.Code)
+	})
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+	"cloudpan/internal/service/sso"
+)
+
+// SSOCallbackRequest 单点登录回调请求，前端回调页面原样提交身份提供方返回的参数
+type SSOCallbackRequest struct {
+	State string `json:"state" binding:"required" example:"Jq3r0cFh8o0bX1Zs5QeWgA"` // 发起登录时返回的state
+	Code  string `json:"code" binding:"required" example:"SplxlOBeZQQYbYS6WxSbIA"`  // 身份提供方返回的授权码
+}
+
+// DiscoverSSO 按邮箱查询单点登录
+//
+// @Summary 查询单点登录
+// @Description 登录页输入邮箱后调用，返回邮箱域名是否配置了企业单点登录以及是否禁止密码登录。只有通过DNS验证的域名才会返回sso=true
+// @Tags 认证
+// @Produce json
+// @Param email query string true "用户邮箱"
+// @Success 200 {object} utils.Response{data=sso.Discovery} "查询成功"
+// @Failure 404 {object} utils.Response "未启用单点登录"
+// @Router /api/v1/auth/sso/discover [get]
+func (h *UserLoginHandler) DiscoverSSO(c *gin.Context) {
+	if h.sso == nil {
+		utils.ErrorWithMessage(c, utils.CodeNotFound, "未启用单点登录")
+		return
+	}
+	discovery, err := h.sso.Discover(c.Request.Context(), c.Query("email"))
+	if err != nil {
+		respondServiceError(c, err, "查询单点登录失败")
+		return
+	}
+	utils.Success(c, discovery)
+}
+
+// AuthorizeSSO 发起单点登录
+//
+// @Summary 发起单点登录
+// @Description 按邮箱域名或租户标识找到身份提供方，返回浏览器需要跳转的授权地址。授权地址带有state、nonce和PKCE参数，登录请求在有效期内只能完成一次
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param request body sso.AuthorizeRequest true "邮箱或租户标识"
+// @Success 200 {object} utils.Response{data=sso.Authorization} "授权地址"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 404 {object} utils.Response "未找到可用的单点登录配置"
+// @Failure 500 {object} utils.Response "身份提供方暂时不可用"
+// @Router /api/v1/auth/sso/authorize [post]
+func (h *UserLoginHandler) AuthorizeSSO(c *gin.Context) {
+	if h.sso == nil {
+		utils.ErrorWithMessage(c, utils.CodeNotFound, "未启用单点登录")
+		return
+	}
+	var req sso.AuthorizeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+		return
+	}
+
+	authorization, err := h.sso.Authorize(c.Request.Context(), &req)
+	if err != nil {
+		respondServiceError(c, err, "发起单点登录失败")
+		return
+	}
+	utils.Success(c, authorization)
+}
+
+// SSOCallback 完成单点登录
+//
+// @Summary 完成单点登录
+// @Description 前端回调页面提交身份提供方返回的state和授权码，服务端换取并验证ID令牌后登录。首次登录时关联同邮箱账户或即时开通账户，角色按身份提供方的组映射。单点登录不再要求两步验证
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param request body SSOCallbackRequest true "state和授权码"
+// @Success 200 {object} utils.Response{data=LoginResponse} "登录成功"
+// @Failure 400 {object} utils.Response "请求参数错误"
+// @Failure 401 {object} utils.Response "登录请求已失效、身份提供方拒绝或账户无法关联"
+// @Failure 403 {object} utils.Response{data=PendingDeletionInfo} "账户已计划删除(code=1026)"
+// @Failure 500 {object} utils.Response "内部服务器错误"
+// @Router /api/v1/auth/sso/callback [post]
+func (h *UserLoginHandler) SSOCallback(c *gin.Context) {
+	if h.sso == nil {
+		utils.ErrorWithMessage(c, utils.CodeNotFound, "未启用单点登录")
+		return
+	}
+	var req SSOCallbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithMessage(c, utils.CodeBadRequest, "请求参数格式错误")
+		return
+	}
+
+	result, err := h.sso.Complete(c.Request.Context(), req.State, req.Code)
+	if err != nil {
+		if errors.Is(err, sso.ErrLoginFailed) {
+			h.logger.Warn("SSO login failed", zap.Error(err), zap.String("ip", c.ClientIP()))
+			utils.ErrorWithMessage(c, utils.CodeUnauthorized, err.Error())
+			return
+		}
+		h.logger.Error("Failed to complete SSO login", zap.Error(err), zap.String("ip", c.ClientIP()))
+		respondServiceError(c, err, "单点登录失败")
+		return
+	}
+	account := result.User
+
+	if account.IsPendingDeletion(time.Now()) {
+		utils.ErrorWithData(c, utils.CodePendingDeletion, "账户已计划删除，重新激活后可继续使用",
+			PendingDeletionInfo{DeletionScheduledAt: account.DeletionScheduledAt.Format(time.RFC3339)})
+		return
+	}
+	if err := h.checkLoginStatus(c, account); err != nil {
+		h.logger.Warn("SSO user status check failed",
+			zap.Uint("user_id", account.ID),
+			zap.String("status", account.Status),
+			zap.Error(err),
+			zap.String("ip", c.ClientIP()))
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, err.Error())
+		return
+	}
+
+	if response, ok := h.issueTokens(c, account, result.Role, result.RememberMe); ok {
+		h.logger.Info("User SSO login successful",
+			zap.Uint("user_id", account.ID),
+			zap.String("tenant", result.Tenant),
+			zap.String("role", result.Role),
+			zap.Bool("provisioned", result.Provisioned),
+			zap.String("ip", c.ClientIP()))
+		utils.SuccessWithMessage(c, "登录成功", response)
+	}
+}
+
+// requireSSO 邮箱域名开启强制SSO时写入响应并返回true，禁止使用密码登录
+func (h *UserLoginHandler) requireSSO(c *gin.Context, account *models.User) bool {
+	if h.sso == nil {
+		return false
+	}
+	discovery, err := h.sso.CheckPasswordLogin(c.Request.Context(), account.Email)
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, sso.ErrSSORequired):
+		h.logger.Info("Password login rejected by SSO enforcement",
+			zap.Uint("user_id", account.ID),
+			zap.String("tenant", discovery.Tenant),
+			zap.String("ip", c.ClientIP()))
+		utils.ErrorWithData(c, utils.CodeSSORequired, "该邮箱域名已启用企业单点登录，请使用SSO登录", discovery)
+		return true
+	default:
+		// 查询失败时拒绝登录，以免绕过强制SSO
+		h.logger.Error("Failed to check SSO enforcement", zap.Uint("user_id", account.ID), zap.Error(err))
+		utils.InternalErrorWithMessage(c, "登录失败")
+		return true
+	}
+}
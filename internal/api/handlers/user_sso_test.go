@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"cloudpan/internal/pkg/cache"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+	"cloudpan/internal/service/sso"
+)
+
+// stubSSOService 返回固定结果的单点登录服务
+type stubSSOService struct {
+	sso.Service
+	providers map[uint]*models.SSOProvider
+	discovery *sso.Discovery
+	result    *sso.LoginResult
+	err       error
+}
+
+func (s *stubSSOService) Discover(_ context.Context, _ string) (*sso.Discovery, error) {
+	if s.discovery == nil {
+		return &sso.Discovery{}, nil
+	}
+	return s.discovery, nil
+}
+
+func (s *stubSSOService) CheckPasswordLogin(_ context.Context, _ string) (*sso.Discovery, error) {
+	if s.discovery != nil && s.discovery.Enforced {
+		return s.discovery, pkgErrors.WrapError(sso.ErrSSORequired, "该邮箱域名已启用企业单点登录，请使用SSO登录")
+	}
+	return &sso.Discovery{}, nil
+}
+
+func (s *stubSSOService) Complete(_ context.Context, _, _ string) (*sso.LoginResult, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.result, nil
+}
+
+func (s *stubSSOService) GetProvider(_ context.Context, id uint) (*models.SSOProvider, error) {
+	provider, ok := s.providers[id]
+	if !ok {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "身份提供方不存在")
+	}
+	return provider, nil
+}
+
+func (s *stubSSOService) CreateProvider(_ context.Context, req *sso.ProviderRequest) (*models.SSOProvider, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	provider := &models.SSOProvider{Tenant: req.Tenant, Name: req.Name, Issuer: req.Issuer, ClientID: req.ClientID, IsActive: true}
+	provider.ID = 5
+	if req.ClientSecret != nil {
+		provider.ClientSecret = *req.ClientSecret
+	}
+	return provider, nil
+}
+
+func (s *stubSSOService) UpdateProvider(_ context.Context, id uint, req *sso.ProviderRequest) (*models.SSOProvider, error) {
+	updated := *s.providers[id]
+	updated.Name = req.Name
+	if req.EnforceSSO != nil {
+		updated.EnforceSSO = *req.EnforceSSO
+	}
+	return &updated, nil
+}
+
+func TestUserLoginHandler_SSO(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	post := func(handle gin.HandlerFunc, body interface{}) (*httptest.ResponseRecorder, utils.Response) {
+		reqBody, _ := json.Marshal(body)
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/api/v1/auth/sso/callback", bytes.NewBuffer(reqBody))
+		c.Request.Header.Set("Content-Type", "application/json")
+		handle(c)
+		return w, decodeShareResponse(t, w)
+	}
+	setup := func(service *stubSSOService) (*UserLoginHandler, *MockLoginUserService) {
+		mockUserService := &MockLoginUserService{}
+		handler := setupTestLoginHandler(mockUserService)
+		handler.SetRefreshTokenStore(cache.NewMemoryRefreshTokenStore())
+		handler.SetTwoFactorService(&stubTwoFactorService{enabled: true})
+		handler.SetSSOService(service)
+		return handler, mockUserService
+	}
+	callback := SSOCallbackRequest{State: "state", Code: "code"}
+
+	t.Run("回调成功签发映射角色的令牌且不要求两步验证", func(t *testing.T) {
+		handler, _ := setup(&stubSSOService{result: &sso.LoginResult{User: setupTestUser(), Role: "admin", Tenant: "acme"}})
+
+		w, resp := post(handler.SSOCallback, callback)
+		require.Equal(t, http.StatusOK, w.Code)
+		data, ok := resp.Data.(map[string]interface{})
+		require.True(t, ok)
+		claims, err := handler.jwtManager.ValidateToken(data["access_token"].(string))
+		require.NoError(t, err)
+		assert.Equal(t, "admin", claims.Role)
+		assert.Equal(t, "admin", data["user"].(map[string]interface{})["role"])
+	})
+
+	t.Run("身份提供方拒绝时返回401", func(t *testing.T) {
+		handler, _ := setup(&stubSSOService{err: pkgErrors.WrapError(sso.ErrLoginFailed, "登录请求已失效，请重新登录")})
+
+		w, resp := post(handler.SSOCallback, callback)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, resp.Message, "重新登录")
+	})
+
+	t.Run("计划删除的账户不能登录", func(t *testing.T) {
+		account := setupTestUser()
+		scheduled := time.Now().Add(24 * time.Hour)
+		account.Status = "deleted"
+		account.DeletionScheduledAt = &scheduled
+		handler, _ := setup(&stubSSOService{result: &sso.LoginResult{User: account, Role: "user"}})
+
+		w, resp := post(handler.SSOCallback, callback)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Equal(t, utils.CodePendingDeletion, resp.Code)
+	})
+
+	t.Run("强制SSO的域名禁止密码登录", func(t *testing.T) {
+		handler, mockUserService := setup(&stubSSOService{discovery: &sso.Discovery{SSO: true, Tenant: "acme", Enforced: true}})
+		mockUserService.On("GetUserByEmail", mock.Anything, "test@example.com").Return(setupTestUser(), nil)
+
+		w, resp := post(handler.Login, LoginRequest{Identifier: "test@example.com", Password: "testPassword123!"})
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Equal(t, utils.CodeSSORequired, resp.Code)
+		assert.Equal(t, "acme", resp.Data.(map[string]interface{})["tenant"])
+
+		// 密码错误时仍返回认证失败，不泄露强制SSO配置
+		w, resp = post(handler.Login, LoginRequest{Identifier: "test@example.com", Password: "wrong-password"})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Nil(t, resp.Data)
+	})
+
+	t.Run("未启用单点登录", func(t *testing.T) {
+		handler := setupTestLoginHandler(&MockLoginUserService{})
+
+		w, _ := post(handler.SSOCallback, callback)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+// stubLDAPAuthenticator 返回固定结果的目录认证
+type stubLDAPAuthenticator struct {
+	result *sso.LDAPLoginResult
+	err    error
+	calls  int
+}
+
+func (s *stubLDAPAuthenticator) Authenticate(_ context.Context, _, _ string) (*sso.LDAPLoginResult, error) {
+	s.calls++
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.result, nil
+}
+
+func TestUserLoginHandler_LDAP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	post := func(handle gin.HandlerFunc, body interface{}) (*httptest.ResponseRecorder, utils.Response) {
+		reqBody, _ := json.Marshal(body)
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/api/v1/login", bytes.NewBuffer(reqBody))
+		c.Request.Header.Set("Content-Type", "application/json")
+		handle(c)
+		return w, decodeShareResponse(t, w)
+	}
+	setup := func(authenticator *stubLDAPAuthenticator) (*UserLoginHandler, *MockLoginUserService) {
+		mockUserService := &MockLoginUserService{}
+		handler := setupTestLoginHandler(mockUserService)
+		handler.SetRefreshTokenStore(cache.NewMemoryRefreshTokenStore())
+		handler.SetLDAPAuthenticator(authenticator)
+		return handler, mockUserService
+	}
+	accessRole := func(t *testing.T, handler *UserLoginHandler, resp utils.Response) string {
+		data, ok := resp.Data.(map[string]interface{})
+		require.True(t, ok)
+		claims, err := handler.jwtManager.ValidateToken(data["access_token"].(string))
+		require.NoError(t, err)
+		return claims.Role
+	}
+	login := LoginRequest{Identifier: "test@example.com", Password: "testPassword123!"}
+
+	t.Run("目录认证成功签发组映射角色的令牌", func(t *testing.T) {
+		handler, mockUserService := setup(&stubLDAPAuthenticator{result: &sso.LDAPLoginResult{User: setupTestUser(), Role: "admin"}})
+
+		w, resp := post(handler.Login, LoginRequest{Identifier: "test@example.com", Password: "directory-password"})
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "admin", accessRole(t, handler, resp))
+		mockUserService.AssertNotCalled(t, "GetUserByEmail", mock.Anything, mock.Anything)
+	})
+
+	t.Run("两步验证通过后沿用目录映射的角色", func(t *testing.T) {
+		handler, _ := setup(&stubLDAPAuthenticator{result: &sso.LDAPLoginResult{User: setupTestUser(), Role: "moderator"}})
+		handler.SetTwoFactorService(&stubTwoFactorService{enabled: true, code: "123456"})
+		handler.SetLoginChallengeStore(cache.NewMemoryLoginChallengeStore())
+		mockUserService := handler.userService.(*MockLoginUserService)
+		mockUserService.On("GetUserByID", mock.Anything, uint(1)).Return(setupTestUser(), nil)
+
+		_, resp := post(handler.Login, login)
+		require.Equal(t, utils.CodeTwoFactorRequired, resp.Code)
+		token := resp.Data.(map[string]interface{})["challenge_token"].(string)
+
+		w, resp := post(handler.VerifyTwoFactor, TwoFactorLoginRequest{ChallengeToken: token, Code: "123456"})
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "moderator", accessRole(t, handler, resp))
+	})
+
+	t.Run("目录中没有该用户或目录不可用时回退到本地账户", func(t *testing.T) {
+		for _, err := range []error{sso.ErrLDAPUserNotFound, pkgErrors.WrapError(sso.ErrLDAPUnavailable, "目录服务暂时不可用")} {
+			authenticator := &stubLDAPAuthenticator{err: err}
+			handler, mockUserService := setup(authenticator)
+			mockUserService.On("GetUserByEmail", mock.Anything, "test@example.com").Return(setupTestUser(), nil)
+
+			w, resp := post(handler.Login, login)
+			require.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, defaultRole, accessRole(t, handler, resp))
+			assert.Equal(t, 1, authenticator.calls)
+		}
+	})
+
+	t.Run("目录密码错误时不回退到本地账户", func(t *testing.T) {
+		handler, mockUserService := setup(&stubLDAPAuthenticator{err: pkgErrors.WrapError(sso.ErrLoginFailed, "用户名或密码错误")})
+
+		w, resp := post(handler.Login, login)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, resp.Message, "用户名或密码错误")
+		mockUserService.AssertNotCalled(t, "GetUserByEmail", mock.Anything, mock.Anything)
+	})
+}
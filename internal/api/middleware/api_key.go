@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/cache"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/ratelimit"
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+)
+
+// HeaderAPIKey API密钥请求头
+const HeaderAPIKey = "X-API-Key"
+
+// TokenTypeAPIKey API密钥认证的请求写入上下文的令牌类型
+const TokenTypeAPIKey = "api_key"
+
+const (
+	// apiKeyRole API密钥请求的角色，密钥不能访问管理接口
+	apiKeyRole = "user"
+	// apiKeyShareRoutePrefix 需要share权限范围的路由前缀
+	apiKeyShareRoutePrefix = "/api/v1/shares"
+)
+
+// APIKeyAuthenticator API密钥认证，由 user.APIKeyService 实现
+//
+// 密钥无效、已过期或所有者不可用时返回 pkgErrors.ErrPermissionDenied，其他错误按认证服务故障处理
+type APIKeyAuthenticator interface {
+	Authenticate(ctx context.Context, key, ip string) (*models.APIKey, *models.User, error)
+}
+
+// APIKeyAuth API密钥认证配置
+type APIKeyAuth struct {
+	Authenticator APIKeyAuthenticator // 密钥认证
+	Bucket        ratelimit.Bucket    // 限流令牌桶，为nil时不限流
+	RateLimit     RateLimitRule       // 每个密钥的限流规则，键由 cache.Keys.APIRateLimit 构造
+}
+
+var (
+	defaultAPIKeyAuthMu sync.RWMutex
+	defaultAPIKeyAuth   *APIKeyAuth
+)
+
+// SetDefaultAPIKeyAuth 设置全局API密钥认证配置，为nil时不接受API密钥
+func SetDefaultAPIKeyAuth(keys *APIKeyAuth) {
+	defaultAPIKeyAuthMu.Lock()
+	defer defaultAPIKeyAuthMu.Unlock()
+	defaultAPIKeyAuth = keys
+}
+
+// DefaultAPIKeyAuth 返回全局API密钥认证配置，未启用时返回nil
+func DefaultAPIKeyAuth() *APIKeyAuth {
+	defaultAPIKeyAuthMu.RLock()
+	defer defaultAPIKeyAuthMu.RUnlock()
+	return defaultAPIKeyAuth
+}
+
+// SetAPIKeyAuth 设置API密钥认证配置，未设置时使用全局配置
+func (auth *AuthMiddleware) SetAPIKeyAuth(keys *APIKeyAuth) {
+	auth.apiKeys = keys
+}
+
+// APIKeyScopeFor 返回请求需要的API密钥权限范围
+//
+// 分享管理接口需要share，其他接口查询类请求需要read，修改数据的请求需要write
+func APIKeyScopeFor(method, route string) string {
+	if route == apiKeyShareRoutePrefix || strings.HasPrefix(route, apiKeyShareRoutePrefix+"/") {
+		return models.APIKeyScopeShare
+	}
+	if isReadOnlyMethod(method) {
+		return models.APIKeyScopeRead
+	}
+	return models.APIKeyScopeWrite
+}
+
+// authenticateAPIKey 认证API密钥并检查权限范围和限流，失败时写入错误响应并返回false
+func (auth *AuthMiddleware) authenticateAPIKey(c *gin.Context, key string) bool {
+	keys := auth.apiKeys
+	if keys == nil {
+		keys = DefaultAPIKeyAuth()
+	}
+	if keys == nil || keys.Authenticator == nil {
+		utils.ErrorWithMessage(c, utils.CodeUnauthorized, "未启用API密钥认证")
+		return false
+	}
+
+	apiKey, owner, err := keys.Authenticator.Authenticate(c.Request.Context(), key, c.ClientIP())
+	if err != nil {
+		if errors.Is(err, pkgErrors.ErrPermissionDenied) {
+			auth.logger.Warn("Invalid API key", zap.String("ip", c.ClientIP()))
+			utils.ErrorWithMessage(c, utils.CodeUnauthorized, "API密钥无效或已过期")
+			return false
+		}
+		auth.logger.Error("Failed to authenticate API key", zap.Error(err), zap.String("ip", c.ClientIP()))
+		utils.ErrorWithMessage(c, utils.CodeInternalError, "API密钥认证失败")
+		return false
+	}
+
+	route := c.FullPath()
+	if route == "" {
+		route = c.Request.URL.Path
+	}
+	if scope := APIKeyScopeFor(c.Request.Method, route); !apiKey.HasScope(scope) {
+		auth.logger.Warn("API key scope denied",
+			zap.Uint("user_id", owner.ID),
+			zap.Uint("api_key_id", apiKey.ID),
+			zap.String("required_scope", scope),
+			zap.String("method", c.Request.Method),
+			zap.String("path", route))
+		utils.ErrorWithMessage(c, utils.CodeForbidden, "API密钥没有"+scope+"权限")
+		return false
+	}
+
+	if keys.Bucket != nil {
+		limitKey := cache.Keys.APIRateLimit(strconv.FormatUint(uint64(apiKey.ID), 10), globalRateLimitScope)
+		if !allowRequest(c, keys.Bucket, limitKey, keys.RateLimit, auth.logger) {
+			return false
+		}
+	}
+
+	c.Set(UserIDContextKey, uint64(owner.ID))
+	c.Set(UsernameContextKey, owner.Username)
+	c.Set(EmailContextKey, owner.Email)
+	c.Set(RoleContextKey, apiKeyRole)
+	c.Set(TokenTypeContextKey, TokenTypeAPIKey)
+	c.Set(APIKeyIDContextKey, uint64(apiKey.ID))
+	return true
+}
@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/ratelimit"
+	"cloudpan/internal/repository/models"
+)
+
+// stubAPIKeyAuthenticator 只接受固定密钥，权限范围为 scopes
+type stubAPIKeyAuthenticator struct {
+	scopes string
+	err    error
+}
+
+func (s *stubAPIKeyAuthenticator) Authenticate(_ context.Context, key, _ string) (*models.APIKey, *models.User, error) {
+	if s.err != nil {
+		return nil, nil, s.err
+	}
+	if key != "cpk_valid" {
+		return nil, nil, pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, "API密钥无效或已过期")
+	}
+	apiKey := &models.APIKey{UserID: 7, Scopes: s.scopes}
+	apiKey.ID = 3
+	owner := &models.User{Username: "alice", Email: "alice@example.com"}
+	owner.ID = 7
+	return apiKey, owner, nil
+}
+
+func TestAuthMiddleware_APIKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	authenticator := &stubAPIKeyAuthenticator{scopes: "read"}
+	authMiddleware := setupTestAuthMiddleware()
+	authMiddleware.SetAPIKeyAuth(&APIKeyAuth{
+		Authenticator: authenticator,
+		Bucket:        ratelimit.NewMemoryBucket(),
+		RateLimit:     RateLimitRule{RequestsPerMinute: 60, Burst: 3},
+	})
+
+	router := gin.New()
+	api := router.Group("/api/v1", authMiddleware.RequireAuth())
+	api.GET("/files", func(c *gin.Context) {
+		assert.Equal(t, uint64(7), c.Value(UserIDContextKey))
+		assert.Equal(t, uint64(3), c.Value(APIKeyIDContextKey))
+		assert.Equal(t, "user", c.Value(RoleContextKey))
+		assert.Equal(t, TokenTypeAPIKey, c.Value(TokenTypeContextKey))
+		c.Status(http.StatusOK)
+	})
+	api.POST("/files", func(c *gin.Context) { c.Status(http.StatusOK) })
+	api.GET("/shares/templates", func(c *gin.Context) { c.Status(http.StatusOK) })
+	api.POST("/change-password", authMiddleware.BlockImpersonation(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	call := func(method, path, key string) int {
+		req := httptest.NewRequest(method, path, nil)
+		if key != "" {
+			req.Header.Set(HeaderAPIKey, key)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, call(http.MethodGet, "/api/v1/files", "cpk_valid"))
+	assert.Equal(t, http.StatusUnauthorized, call(http.MethodGet, "/api/v1/files", "cpk_wrong"))
+	assert.Equal(t, http.StatusUnauthorized, call(http.MethodGet, "/api/v1/files", ""))
+	assert.Equal(t, http.StatusForbidden, call(http.MethodPost, "/api/v1/files", "cpk_valid"), "没有write权限")
+	assert.Equal(t, http.StatusForbidden, call(http.MethodGet, "/api/v1/shares/templates", "cpk_valid"), "分享接口需要share权限")
+
+	authenticator.scopes = "read,write,share"
+	assert.Equal(t, http.StatusForbidden, call(http.MethodPost, "/api/v1/change-password", "cpk_valid"), "敏感操作需要登录")
+
+	// 突发容量3，权限范围不足的请求不消耗令牌
+	assert.Equal(t, http.StatusOK, call(http.MethodGet, "/api/v1/files", "cpk_valid"))
+	assert.Equal(t, http.StatusTooManyRequests, call(http.MethodGet, "/api/v1/files", "cpk_valid"))
+
+	authenticator.err = errors.New("database unavailable")
+	assert.Equal(t, http.StatusInternalServerError, call(http.MethodGet, "/api/v1/files", "cpk_valid"))
+}
+
+func TestAuthMiddleware_APIKeyDisabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetDefaultAPIKeyAuth(nil)
+	router := gin.New()
+	router.GET("/api/v1/files", setupTestAuthMiddleware().RequireAuth(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/files", nil)
+	req.Header.Set(HeaderAPIKey, "cpk_valid")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestAPIKeyScopeFor(t *testing.T) {
+	assert.Equal(t, models.APIKeyScopeRead, APIKeyScopeFor(http.MethodGet, "/api/v1/files/:id"))
+	assert.Equal(t, models.APIKeyScopeWrite, APIKeyScopeFor(http.MethodDelete, "/api/v1/files/:id"))
+	assert.Equal(t, models.APIKeyScopeShare, APIKeyScopeFor(http.MethodPost, "/api/v1/shares"))
+	assert.Equal(t, models.APIKeyScopeShare, APIKeyScopeFor(http.MethodGet, "/api/v1/shares/templates"))
+	assert.Equal(t, models.APIKeyScopeRead, APIKeyScopeFor(http.MethodGet, "/api/v1/sharesx"))
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyIDContextKey API密钥认证成功后写入上下文的密钥ID
+const APIKeyIDContextKey = "api_key_id"
+
+// APIUsageRecorder 记录每个请求的API用量
+type APIUsageRecorder interface {
+	RecordRequest(userID, apiKeyID uint, endpoint string, status int, bytesIn, bytesOut int64)
+}
+
+// APIUsage 创建按用户统计API用量的中间件
+//
+// 作为全局中间件注册，在请求处理完成后读取认证中间件写入的用户ID和API密钥ID，
+// 按 方法+路由模板 计数。未登录和未匹配路由的请求不计数
+func APIUsage(recorder APIUsageRecorder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			return
+		}
+		userID := contextUint(c, UserIDContextKey)
+		if userID == 0 {
+			return
+		}
+
+		bytesIn := max(c.Request.ContentLength, 0)
+		bytesOut := int64(max(c.Writer.Size(), 0))
+		recorder.RecordRequest(userID, contextUint(c, APIKeyIDContextKey), c.Request.Method+" "+route,
+			c.Writer.Status(), bytesIn, bytesOut)
+	}
+}
+
+// contextUint 读取上下文中的数字ID，兼容uint和uint64
+func contextUint(c *gin.Context, key string) uint {
+	switch value := c.Value(key).(type) {
+	case uint64:
+		return uint(value)
+	case uint:
+		return value
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// usageCall 记录的一次请求用量
+type usageCall struct {
+	userID, apiKeyID  uint
+	endpoint          string
+	status            int
+	bytesIn, bytesOut int64
+}
+
+// recordingUsageRecorder 记录全部调用的用量记录器
+type recordingUsageRecorder struct {
+	calls []usageCall
+}
+
+func (r *recordingUsageRecorder) RecordRequest(userID, apiKeyID uint, endpoint string, status int, bytesIn, bytesOut int64) {
+	r.calls = append(r.calls, usageCall{userID, apiKeyID, endpoint, status, bytesIn, bytesOut})
+}
+
+func TestAPIUsage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := &recordingUsageRecorder{}
+
+	router := gin.New()
+	router.Use(APIUsage(recorder))
+	authed := func(c *gin.Context) {
+		c.Set("user_id", uint64(7))
+		if c.GetHeader("X-API-Key") != "" {
+			c.Set(APIKeyIDContextKey, uint(3))
+		}
+	}
+	router.POST("/files/:id", authed, func(c *gin.Context) {
+		c.String(http.StatusCreated, "created")
+	})
+	router.GET("/public", func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/files/42", strings.NewReader("hello"))
+	req.Header.Set("X-API-Key", "key")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/public", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+	require.Len(t, recorder.calls, 1, "未登录和未匹配路由的请求不计数")
+	assert.Equal(t, usageCall{
+		userID:   7,
+		apiKeyID: 3,
+		endpoint: "POST /files/:id",
+		status:   http.StatusCreated,
+		bytesIn:  5,
+		bytesOut: 7,
+	}, recorder.calls[0])
+}
@@ -1,17 +1,41 @@
 package middleware
 
 import (
+	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 
+	"cloudpan/internal/pkg/cache"
 	"cloudpan/internal/pkg/utils"
 )
 
+// 认证成功后写入上下文的键，处理器通过 c.Get 读取
+const (
+	UserIDContextKey    = "user_id"    // 用户ID(uint64)
+	UsernameContextKey  = "username"   // 用户名
+	EmailContextKey     = "email"      // 邮箱
+	RoleContextKey      = "role"       // 角色
+	TokenTypeContextKey = "token_type" // 令牌类型，访问路由上始终为access
+	ClaimsContextKey    = "claims"     // 完整的令牌声明(*utils.JWTClaims)
+
+	ImpersonatorIDContextKey = "impersonator_id" // 模拟登录的管理员ID(uint64)，只有模拟登录令牌写入
+)
+
+// 模拟登录响应头，客户端据此显示模拟登录横幅
+const (
+	HeaderImpersonatedBy      = "X-Impersonated-By"       // 发起模拟登录的管理员ID
+	HeaderImpersonationExpiry = "X-Impersonation-Expires" // 模拟登录令牌过期时间(RFC3339)
+)
+
 // AuthMiddleware JWT认证中间件配置
 type AuthMiddleware struct {
 	jwtManager utils.JWTManager
+	tokenStore cache.TokenStore
+	apiKeys    *APIKeyAuth
 	logger     *zap.Logger
 }
 
@@ -28,15 +52,29 @@ func NewAuthMiddleware(secretKey string, logger *zap.Logger) (*AuthMiddleware, e
 	}, nil
 }
 
+// SetTokenStore 设置令牌吊销存储，未设置时使用全局令牌吊销存储
+func (auth *AuthMiddleware) SetTokenStore(store cache.TokenStore) {
+	auth.tokenStore = store
+}
+
 // RequireAuth JWT认证中间件
 //
 // 验证请求头中的JWT Token，如果验证成功则将用户信息存储到上下文中
-// 如果验证失败则返回401错误
+// 如果验证失败则返回401错误。没有Token时接受 X-API-Key 请求头中的API密钥，
+// 按请求检查密钥的权限范围并单独限流
 func (auth *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 从请求头获取Token
 		token := auth.extractToken(c)
 		if token == "" {
+			if key := strings.TrimSpace(c.GetHeader(HeaderAPIKey)); key != "" {
+				if !auth.authenticateAPIKey(c, key) {
+					c.Abort()
+					return
+				}
+				c.Next()
+				return
+			}
 			auth.logger.Warn("Missing authorization token", zap.String("ip", c.ClientIP()))
 			utils.ErrorWithMessage(c, utils.CodeUnauthorized, "缺少认证令牌")
 			c.Abort()
@@ -64,13 +102,22 @@ func (auth *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 			return
 		}
 
-		// 将用户信息存储到上下文
-		c.Set("user_id", claims.UserID)
-		c.Set("username", claims.Username)
-		c.Set("email", claims.Email)
-		c.Set("role", claims.Role)
-		c.Set("claims", claims)
+		// 检查Token是否已被吊销(如管理员强制重置密码)
+		if auth.isRevoked(c, claims) {
+			auth.logger.Warn("Revoked token rejected",
+				zap.Uint64("user_id", claims.UserID),
+				zap.String("ip", c.ClientIP()))
+			utils.ErrorWithMessage(c, utils.CodeUnauthorized, "令牌已失效，请重新登录")
+			c.Abort()
+			return
+		}
+
+		if claims.IsImpersonation() && !auth.allowImpersonatedRequest(c, claims) {
+			c.Abort()
+			return
+		}
 
+		setClaims(c, claims)
 		c.Next()
 	}
 }
@@ -78,7 +125,7 @@ func (auth *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 // OptionalAuth 可选认证中间件
 //
 // 如果提供了有效的Token，则将用户信息存储到上下文中
-// 如果没有提供Token或Token无效，则不进行处理，允许请求继续
+// 如果没有提供Token或Token无效，则不进行处理，允许请求继续；不接受API密钥
 func (auth *AuthMiddleware) OptionalAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 从请求头获取Token
@@ -99,19 +146,18 @@ func (auth *AuthMiddleware) OptionalAuth() gin.HandlerFunc {
 			return
 		}
 
-		// 检查Token类型
-		if claims.TokenType != "access" {
+		// 检查Token类型和吊销状态
+		if claims.TokenType != "access" || auth.isRevoked(c, claims) {
 			c.Next()
 			return
 		}
 
-		// 将用户信息存储到上下文
-		c.Set("user_id", claims.UserID)
-		c.Set("username", claims.Username)
-		c.Set("email", claims.Email)
-		c.Set("role", claims.Role)
-		c.Set("claims", claims)
+		if claims.IsImpersonation() && !auth.allowImpersonatedRequest(c, claims) {
+			c.Abort()
+			return
+		}
 
+		setClaims(c, claims)
 		c.Next()
 	}
 }
@@ -123,7 +169,7 @@ func (auth *AuthMiddleware) OptionalAuth() gin.HandlerFunc {
 func (auth *AuthMiddleware) RequireRole(requiredRole string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 获取用户角色
-		role, exists := c.Get("role")
+		role, exists := c.Get(RoleContextKey)
 		if !exists {
 			auth.logger.Warn("Missing user role in context", zap.String("ip", c.ClientIP()))
 			utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户认证信息缺失")
@@ -141,7 +187,7 @@ func (auth *AuthMiddleware) RequireRole(requiredRole string) gin.HandlerFunc {
 
 		// 验证角色权限
 		if !auth.hasRole(userRole, requiredRole) {
-			userID, _ := c.Get("user_id")
+			userID, _ := c.Get(UserIDContextKey)
 			auth.logger.Warn("Insufficient role permissions",
 				zap.Any("user_id", userID),
 				zap.String("user_role", userRole),
@@ -160,7 +206,7 @@ func (auth *AuthMiddleware) RequireRole(requiredRole string) gin.HandlerFunc {
 func (auth *AuthMiddleware) RequireAnyRole(roles ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 获取用户角色
-		role, exists := c.Get("role")
+		role, exists := c.Get(RoleContextKey)
 		if !exists {
 			auth.logger.Warn("Missing user role in context", zap.String("ip", c.ClientIP()))
 			utils.ErrorWithMessage(c, utils.CodeUnauthorized, "用户认证信息缺失")
@@ -186,7 +232,7 @@ func (auth *AuthMiddleware) RequireAnyRole(roles ...string) gin.HandlerFunc {
 		}
 
 		if !hasPermission {
-			userID, _ := c.Get("user_id")
+			userID, _ := c.Get(UserIDContextKey)
 			auth.logger.Warn("Insufficient role permissions",
 				zap.Any("user_id", userID),
 				zap.String("user_role", userRole),
@@ -201,6 +247,125 @@ func (auth *AuthMiddleware) RequireAnyRole(roles ...string) gin.HandlerFunc {
 	}
 }
 
+// BlockImpersonation 禁止模拟登录令牌访问的中间件
+//
+// 需要先使用RequireAuth中间件进行认证，用于修改密码、邮箱、两步验证等敏感操作，
+// 管理员模拟用户时即使不是只读模式也不能执行这些操作；API密钥同样不能执行
+func (auth *AuthMiddleware) BlockImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString(TokenTypeContextKey) == TokenTypeAPIKey {
+			auth.logger.Warn("Sensitive action blocked for API key",
+				zap.Any("user_id", c.Value(UserIDContextKey)),
+				zap.Any("api_key_id", c.Value(APIKeyIDContextKey)),
+				zap.String("method", c.Request.Method),
+				zap.String("path", c.FullPath()),
+				zap.String("ip", c.ClientIP()))
+			utils.ErrorWithMessage(c, utils.CodeForbidden, "API密钥不能执行此操作，请登录后操作")
+			c.Abort()
+			return
+		}
+		if _, impersonated := c.Get(ImpersonatorIDContextKey); impersonated {
+			userID, _ := c.Get(UserIDContextKey)
+			impersonatorID, _ := c.Get(ImpersonatorIDContextKey)
+			auth.logger.Warn("Sensitive action blocked under impersonation",
+				zap.Any("user_id", userID),
+				zap.Any("impersonator_id", impersonatorID),
+				zap.String("method", c.Request.Method),
+				zap.String("path", c.FullPath()),
+				zap.String("ip", c.ClientIP()))
+			utils.ErrorWithMessage(c, utils.CodeForbidden, "模拟登录时不能执行此操作")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// allowImpersonatedRequest 处理模拟登录令牌的请求，不允许时写入错误响应并返回false
+//
+// 每个请求都写入模拟登录响应头并记录日志，只读模式下拒绝查询以外的请求
+func (auth *AuthMiddleware) allowImpersonatedRequest(c *gin.Context, claims *utils.JWTClaims) bool {
+	c.Header(HeaderImpersonatedBy, strconv.FormatUint(claims.ImpersonatorID, 10))
+	if claims.ExpiresAt != nil {
+		c.Header(HeaderImpersonationExpiry, claims.ExpiresAt.Time.UTC().Format(time.RFC3339))
+	}
+
+	fields := []zap.Field{
+		zap.Uint64("user_id", claims.UserID),
+		zap.Uint64("impersonator_id", claims.ImpersonatorID),
+		zap.Bool("read_only", claims.ReadOnly),
+		zap.String("method", c.Request.Method),
+		zap.String("path", c.Request.URL.Path),
+		zap.String("ip", c.ClientIP()),
+	}
+	if claims.ReadOnly && !isReadOnlyMethod(c.Request.Method) {
+		auth.logger.Warn("Write request rejected under read-only impersonation", fields...)
+		utils.ErrorWithMessage(c, utils.CodeForbidden, "只读模拟登录不能修改数据")
+		return false
+	}
+	auth.logger.Info("Impersonated request", fields...)
+	return true
+}
+
+// isReadOnlyMethod 判断是否为不修改数据的请求方法
+func isReadOnlyMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// setClaims 将已验证的令牌声明存储到上下文
+func setClaims(c *gin.Context, claims *utils.JWTClaims) {
+	c.Set(UserIDContextKey, claims.UserID)
+	c.Set(UsernameContextKey, claims.Username)
+	c.Set(EmailContextKey, claims.Email)
+	c.Set(RoleContextKey, claims.Role)
+	c.Set(TokenTypeContextKey, claims.TokenType)
+	c.Set(ClaimsContextKey, claims)
+	if claims.IsImpersonation() {
+		c.Set(ImpersonatorIDContextKey, claims.ImpersonatorID)
+	}
+}
+
+// IdentifyUser 从请求的访问令牌中识别用户，不写入上下文也不检查吊销状态
+//
+// 供在认证中间件之前运行的中间件(如限流)按用户区分请求，令牌缺失或无效时返回false
+func (auth *AuthMiddleware) IdentifyUser(c *gin.Context) (uint, bool) {
+	token := auth.extractToken(c)
+	if token == "" {
+		return 0, false
+	}
+	claims, err := auth.jwtManager.ValidateToken(token)
+	if err != nil || claims.TokenType != "access" || claims.UserID == 0 {
+		return 0, false
+	}
+	return uint(claims.UserID), true
+}
+
+// isRevoked 检查Token是否已被吊销
+//
+// 吊销存储不可用时放行并记录错误，避免Redis故障导致全部请求认证失败
+func (auth *AuthMiddleware) isRevoked(c *gin.Context, claims *utils.JWTClaims) bool {
+	store := auth.tokenStore
+	if store == nil {
+		store = cache.DefaultTokenStore()
+	}
+	if store == nil || claims.IssuedAt == nil {
+		return false
+	}
+
+	revoked, err := cache.IsTokenRevoked(c.Request.Context(), store, claims.UserID, claims.IssuedAt.Time)
+	if err != nil {
+		auth.logger.Error("Failed to check token revocation",
+			zap.Uint64("user_id", claims.UserID),
+			zap.Error(err))
+		return false
+	}
+	return revoked
+}
+
 // extractToken 从请求头中提取Token
 func (auth *AuthMiddleware) extractToken(c *gin.Context) string {
 	// 从Authorization头获取Token
@@ -247,7 +412,7 @@ func (auth *AuthMiddleware) hasRole(userRole, requiredRole string) bool {
 
 // GetCurrentUser 获取当前用户信息的辅助函数
 func GetCurrentUser(c *gin.Context) *utils.JWTClaims {
-	claims, exists := c.Get("claims")
+	claims, exists := c.Get(ClaimsContextKey)
 	if !exists {
 		return nil
 	}
@@ -262,7 +427,7 @@ func GetCurrentUser(c *gin.Context) *utils.JWTClaims {
 
 // GetCurrentUserID 获取当前用户ID的辅助函数
 func GetCurrentUserID(c *gin.Context) (uint64, bool) {
-	userID, exists := c.Get("user_id")
+	userID, exists := c.Get(UserIDContextKey)
 	if !exists {
 		return 0, false
 	}
@@ -273,6 +438,6 @@ func GetCurrentUserID(c *gin.Context) (uint64, bool) {
 
 // IsAuthenticated 检查用户是否已认证
 func IsAuthenticated(c *gin.Context) bool {
-	_, exists := c.Get("user_id")
+	_, exists := c.Get(UserIDContextKey)
 	return exists
 }
@@ -1,14 +1,17 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
 
+	"cloudpan/internal/pkg/cache"
 	"cloudpan/internal/pkg/utils"
 )
 
@@ -85,6 +88,10 @@ func TestAuthMiddleware_RequireAuth(t *testing.T) {
 			assert.True(t, exists)
 			assert.Equal(t, "user", role)
 
+			tokenType, exists := c.Get(TokenTypeContextKey)
+			assert.True(t, exists)
+			assert.Equal(t, "access", tokenType)
+
 			c.JSON(http.StatusOK, gin.H{"message": "success"})
 		})
 
@@ -181,6 +188,38 @@ func TestAuthMiddleware_RequireAuth(t *testing.T) {
 		// 验证结果
 		assert.Equal(t, http.StatusUnauthorized, w.Code)
 	})
+
+	t.Run("已吊销的访问令牌", func(t *testing.T) {
+		accessToken, _, err := generateTestTokens()
+		assert.NoError(t, err)
+
+		revoking := setupTestAuthMiddleware()
+		store := cache.NewMemoryTokenStore(time.Hour)
+		revoking.SetTokenStore(store)
+
+		router := gin.New()
+		router.Use(revoking.RequireAuth())
+		router.GET("/protected", func(c *gin.Context) {
+			c.JSON(http.StatusOK, gin.H{"message": "success"})
+		})
+
+		request := func() int {
+			req := httptest.NewRequest("GET", "/protected", nil)
+			req.Header.Set("Authorization", "Bearer "+accessToken)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			return w.Code
+		}
+
+		assert.Equal(t, http.StatusOK, request())
+
+		// 吊销其他用户的令牌不影响当前用户
+		assert.NoError(t, store.RevokeUserTokens(context.Background(), 2, time.Now()))
+		assert.Equal(t, http.StatusOK, request())
+
+		assert.NoError(t, store.RevokeUserTokens(context.Background(), 1, time.Now()))
+		assert.Equal(t, http.StatusUnauthorized, request())
+	})
 }
 
 func TestAuthMiddleware_OptionalAuth(t *testing.T) {
@@ -536,3 +575,84 @@ func TestAuthMiddleware_HasRole(t *testing.T) {
 		assert.False(t, authMiddleware.hasRole("user", "custom"))
 	})
 }
+
+func TestAuthMiddleware_IdentifyUser(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	authMiddleware := setupTestAuthMiddleware()
+	accessToken, refreshToken, err := generateTestTokens()
+	assert.NoError(t, err)
+
+	identify := func(token string) (uint, bool) {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "/", nil)
+		if token != "" {
+			c.Request.Header.Set("Authorization", "Bearer "+token)
+		}
+		return authMiddleware.IdentifyUser(c)
+	}
+
+	userID, ok := identify(accessToken)
+	assert.True(t, ok)
+	assert.Equal(t, uint(1), userID)
+
+	// 刷新令牌和无效令牌不识别为用户
+	_, ok = identify(refreshToken)
+	assert.False(t, ok)
+	_, ok = identify("invalid")
+	assert.False(t, ok)
+	_, ok = identify("")
+	assert.False(t, ok)
+}
+
+func TestAuthMiddleware_Impersonation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	authMiddleware := setupTestAuthMiddleware()
+	jwtManager, err := utils.NewDefaultJWTManager(testJWTSecret)
+	assert.NoError(t, err)
+
+	readOnlyToken, err := jwtManager.GenerateImpersonationToken(1, "testuser", "test@example.com", "user", 9, true, 15*time.Minute)
+	assert.NoError(t, err)
+	writableToken, err := jwtManager.GenerateImpersonationToken(1, "testuser", "test@example.com", "user", 9, false, 15*time.Minute)
+	assert.NoError(t, err)
+	accessToken, _, err := generateTestTokens()
+	assert.NoError(t, err)
+
+	router := gin.New()
+	router.Use(authMiddleware.RequireAuth())
+	ok := func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"message": "success"}) }
+	router.GET("/files", ok)
+	router.POST("/files", ok)
+	router.POST("/change-password", authMiddleware.BlockImpersonation(), ok)
+
+	serve := func(method, path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("模拟登录返回横幅响应头", func(t *testing.T) {
+		w := serve("GET", "/files", readOnlyToken)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "9", w.Header().Get(HeaderImpersonatedBy))
+		expiresAt, err := time.Parse(time.RFC3339, w.Header().Get(HeaderImpersonationExpiry))
+		assert.NoError(t, err)
+		assert.WithinDuration(t, time.Now().Add(15*time.Minute), expiresAt, time.Minute)
+
+		// 普通令牌不返回模拟登录响应头
+		w = serve("GET", "/files", accessToken)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get(HeaderImpersonatedBy))
+	})
+
+	t.Run("只读模式拒绝写请求", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, serve("POST", "/files", readOnlyToken).Code)
+		assert.Equal(t, http.StatusOK, serve("POST", "/files", writableToken).Code)
+	})
+
+	t.Run("模拟登录禁止敏感操作", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, serve("POST", "/change-password", writableToken).Code)
+		assert.Equal(t, http.StatusOK, serve("POST", "/change-password", accessToken).Code)
+	})
+}
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -41,6 +42,7 @@ func DefaultCORSOptions() *CORSOptions {
 			"Accept",
 			"Cache-Control",
 			"X-Request-ID",
+			HeaderAPIKey,
 		},
 		ExposedHeaders: []string{
 			"Content-Length",
@@ -53,6 +55,8 @@ func DefaultCORSOptions() *CORSOptions {
 			"Last-Modified",
 			"Pragma",
 			"X-Request-ID",
+			HeaderImpersonatedBy,
+			HeaderImpersonationExpiry,
 		},
 		AllowCredentials: true,
 		MaxAge:           86400, // 24小时
@@ -111,7 +115,7 @@ func setCORSHeaders(c *gin.Context, origin string, opts *CORSOptions) {
 
 	// 设置预检请求缓存时间
 	if opts.MaxAge > 0 {
-		c.Header("Access-Control-Max-Age", string(rune(opts.MaxAge)))
+		c.Header("Access-Control-Max-Age", strconv.Itoa(opts.MaxAge))
 	}
 }
 
@@ -119,6 +123,8 @@ func setCORSHeaders(c *gin.Context, origin string, opts *CORSOptions) {
 func setAllowOriginHeader(c *gin.Context, origin string, allowedOrigins []string) {
 	if isOriginAllowed(origin, allowedOrigins) {
 		c.Header("Access-Control-Allow-Origin", origin)
+		// 按请求来源返回不同的响应头，缓存需要区分来源
+		c.Writer.Header().Add("Vary", "Origin")
 	} else if len(allowedOrigins) == 1 && allowedOrigins[0] == "*" {
 		c.Header("Access-Control-Allow-Origin", "*")
 	}
@@ -169,7 +175,12 @@ func CORSMiddleware() gin.HandlerFunc {
 
 // ProductionCORS 生产环境CORS配置
 func ProductionCORS(allowedOrigins []string) gin.HandlerFunc {
-	opts := &CORSOptions{
+	return CORS(ProductionCORSOptions(allowedOrigins))
+}
+
+// ProductionCORSOptions 生产环境CORS配置选项，只允许指定的源
+func ProductionCORSOptions(allowedOrigins []string) *CORSOptions {
+	return &CORSOptions{
 		AllowedOrigins: allowedOrigins,
 		AllowedMethods: []string{
 			http.MethodGet,
@@ -186,15 +197,16 @@ func ProductionCORS(allowedOrigins []string) gin.HandlerFunc {
 			"X-Requested-With",
 			"Accept",
 			"X-Request-ID",
+			HeaderAPIKey,
 		},
 		ExposedHeaders: []string{
 			"Content-Length",
 			"Content-Type",
 			"X-Request-ID",
+			HeaderImpersonatedBy,
+			HeaderImpersonationExpiry,
 		},
 		AllowCredentials: true,
 		MaxAge:           3600, // 1小时
 	}
-
-	return CORS(opts)
 }
@@ -121,6 +121,34 @@ func TestCORSMiddleware(t *testing.T) {
 	})
 }
 
+func TestCORSMiddleware_Headers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(CORS(ProductionCORSOptions([]string{"https://app.example.com"})))
+	router.GET("/test", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodOptions, "/test", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", "GET")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusNoContent, recorder.Code)
+	assert.Equal(t, "3600", recorder.Header().Get("Access-Control-Max-Age"))
+	assert.Equal(t, "Origin", recorder.Header().Get("Vary"))
+	assert.Contains(t, recorder.Header().Get("Access-Control-Allow-Headers"), HeaderAPIKey)
+
+	// 不允许的源不返回允许头
+	req = httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set("Origin", "https://evil.example.org")
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	assert.Empty(t, recorder.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, recorder.Header().Get("Vary"))
+}
+
 func TestIsOriginAllowed(t *testing.T) {
 	t.Run("TestExactMatch", func(t *testing.T) {
 		allowed := []string{"https://example.com", "https://test.com"}
@@ -11,6 +11,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 
+	"cloudpan/internal/pkg/buildinfo"
 	pkgErrors "cloudpan/internal/pkg/errors"
 	"cloudpan/internal/pkg/logger"
 )
@@ -50,6 +51,7 @@ func DefaultErrorHandlerConfig() ErrorHandlerConfig {
 			pkgErrors.ErrResourceExists:           http.StatusConflict,
 			pkgErrors.ErrOperationNotAllowed:      http.StatusMethodNotAllowed,
 			pkgErrors.ErrQuotaExceeded:            http.StatusForbidden,
+			pkgErrors.ErrTooManyRequests:          http.StatusTooManyRequests,
 			pkgErrors.ErrNetworkTimeout:           http.StatusRequestTimeout,
 			pkgErrors.ErrDatabaseConnectionFailed: http.StatusInternalServerError,
 			pkgErrors.ErrCacheServerDown:          http.StatusInternalServerError,
@@ -87,14 +89,16 @@ func handlePanic(c *gin.Context, err interface{}, cfg ErrorHandlerConfig) {
 	requestID := getRequestID(c)
 
 	// 记录panic日志
+	// 附带构建信息，便于将崩溃关联到具体发布
 	stack := debug.Stack()
-	logger.Logger.Error("Panic recovered",
+	fields := []zap.Field{
 		zap.String("request_id", requestID),
 		zap.String("method", c.Request.Method),
 		zap.String("path", c.Request.URL.Path),
 		zap.Any("panic", err),
 		zap.String("stack", string(stack)),
-	)
+	}
+	logger.Logger.Error("Panic recovered", append(fields, buildinfo.Fields()...)...)
 
 	// 构建错误响应
 	response := ErrorResponse{
@@ -140,14 +144,13 @@ func handleGinErrors(c *gin.Context, cfg ErrorHandlerConfig) {
 	}
 
 	// 添加用户ID（如果存在）
-	if userID, exists := c.Get("user_id"); exists {
-		if uid, ok := userID.(string); ok {
-			fields = append(fields, zap.String("user_id", uid))
-		}
+	if uid := formatUserID(c.Value(UserIDContextKey)); uid != "" {
+		fields = append(fields, zap.String("user_id", uid))
 	}
 
 	switch logLevel {
 	case "error":
+		fields = append(fields, buildinfo.Fields()...)
 		logger.Logger.Error("Request failed", fields...)
 	case "warn":
 		logger.Logger.Warn("Request warning", fields...)
@@ -180,7 +183,7 @@ func handleGinErrors(c *gin.Context, cfg ErrorHandlerConfig) {
 
 // getRequestID 获取请求ID
 func getRequestID(c *gin.Context) string {
-	if requestID, exists := c.Get("request_id"); exists {
+	if requestID, exists := c.Get(RequestIDContextKey); exists {
 		if rid, ok := requestID.(string); ok {
 			return rid
 		}
@@ -243,6 +246,7 @@ func getErrorMessageMap() map[error]string {
 		pkgErrors.ErrResourceExists:           "资源已存在",
 		pkgErrors.ErrOperationNotAllowed:      "操作不被允许",
 		pkgErrors.ErrQuotaExceeded:            "配额超出限制",
+		pkgErrors.ErrTooManyRequests:          "请求过于频繁",
 		pkgErrors.ErrNetworkTimeout:           "网络超时",
 		pkgErrors.ErrDatabaseConnectionFailed: "数据库连接失败",
 		pkgErrors.ErrCacheServerDown:          "缓存服务异常",
@@ -279,6 +283,7 @@ func getErrorTypeMap() map[error]string {
 		pkgErrors.ErrResourceExists:           "resource_exists",
 		pkgErrors.ErrOperationNotAllowed:      "operation_not_allowed",
 		pkgErrors.ErrQuotaExceeded:            "quota_exceeded",
+		pkgErrors.ErrTooManyRequests:          "too_many_requests",
 		pkgErrors.ErrNetworkTimeout:           "network_timeout",
 		pkgErrors.ErrDatabaseConnectionFailed: "database_error",
 		pkgErrors.ErrCacheServerDown:          "cache_error",
@@ -11,6 +11,8 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"gopkg.in/yaml.v3"
+
+	"cloudpan/internal/pkg/i18n"
 )
 
 // I18nConfig 国际化配置
@@ -27,8 +29,8 @@ type I18nConfig struct {
 // DefaultI18nConfig 默认国际化配置
 func DefaultI18nConfig() *I18nConfig {
 	return &I18nConfig{
-		DefaultLanguage:    "zh-CN",
-		SupportedLanguages: []string{"zh-CN", "en-US", "ja-JP"},
+		DefaultLanguage:    i18n.DefaultLanguage,
+		SupportedLanguages: append([]string(nil), i18n.SupportedLanguages...),
 		LanguageHeader:     "Accept-Language",
 		LanguageParam:      "lang",
 		LanguageCookie:     "lang",
@@ -131,6 +133,20 @@ func (i *I18nManager) GetTranslation(lang, key string, args ...interface{}) stri
 	return key
 }
 
+// GetPluralTranslation 获取与数量n对应复数形式的翻译
+//
+// 翻译文件中复数形式按类别写在键的下一级，如 files.count.one 和 files.count.other，
+// 当前类别缺失时依次使用 other 和键本身的翻译。复数规则与邮件模板的 plural 函数共用 i18n.PluralCategory
+func (i *I18nManager) GetPluralTranslation(lang, key string, n float64, args ...interface{}) string {
+	for _, category := range []string{i18n.PluralCategory(lang, n), i18n.PluralOther} {
+		pluralKey := key + "." + category
+		if value := i.GetTranslation(lang, pluralKey, args...); value != pluralKey {
+			return value
+		}
+	}
+	return i.GetTranslation(lang, key, args...)
+}
+
 // getNestedValue 获取嵌套值
 func (i *I18nManager) getNestedValue(translation Translation, key string) string {
 	keys := strings.Split(key, ".")
@@ -231,7 +247,7 @@ func extractLanguage(c *gin.Context, cfg *I18nConfig) string {
 	// 3. 从Header获取
 	if lang == "" {
 		acceptLang := c.GetHeader(cfg.LanguageHeader)
-		lang = parseAcceptLanguage(acceptLang)
+		lang = parseAcceptLanguage(acceptLang, cfg.SupportedLanguages...)
 	}
 
 	// 4. 使用默认语言
@@ -243,43 +259,22 @@ func extractLanguage(c *gin.Context, cfg *I18nConfig) string {
 }
 
 // parseAcceptLanguage 解析Accept-Language头
-func parseAcceptLanguage(acceptLang string) string {
-	if acceptLang == "" {
-		return ""
+//
+// 按权重顺序返回第一个受支持的语言(基础语言相同也算匹配)，都不支持时返回权重最高的语言
+func parseAcceptLanguage(acceptLang string, supportedLanguages ...string) string {
+	languages := i18n.ParseAcceptLanguage(acceptLang)
+	if matched := i18n.Match(languages, supportedLanguages); matched != "" {
+		return matched
 	}
-
-	// 简单解析，取第一个语言标识
-	parts := strings.Split(acceptLang, ",")
-	if len(parts) > 0 {
-		lang := strings.TrimSpace(parts[0])
-		// 移除权重信息
-		if idx := strings.Index(lang, ";"); idx != -1 {
-			lang = lang[:idx]
-		}
-		return lang
+	if len(languages) > 0 {
+		return languages[0]
 	}
-
 	return ""
 }
 
 // normalizeLanguage 标准化语言格式
 func normalizeLanguage(lang string) string {
-	lang = strings.TrimSpace(lang)
-	if lang == "" {
-		return ""
-	}
-
-	// 转换常见格式
-	switch strings.ToLower(lang) {
-	case "zh", "zh-cn", "chinese":
-		return "zh-CN"
-	case "en", "en-us", "english":
-		return "en-US"
-	case "ja", "ja-jp", "japanese":
-		return "ja-JP"
-	default:
-		return lang
-	}
+	return i18n.Normalize(lang)
 }
 
 // isLanguageSupported 检查语言是否支持
@@ -321,6 +316,16 @@ func Translate(c *gin.Context, key string, args ...interface{}) string {
 	return key
 }
 
+// TPlural 按数量n翻译复数形式（简写）
+func TPlural(c *gin.Context, key string, n float64, args ...interface{}) string {
+	if manager, exists := c.Get("i18n_manager"); exists {
+		if i18nManager, ok := manager.(*I18nManager); ok {
+			return i18nManager.GetPluralTranslation(GetLanguage(c), key, n, args...)
+		}
+	}
+	return key
+}
+
 // LanguageInfoResponse 语言信息响应
 type LanguageInfoResponse struct {
 	CurrentLanguage    string   `json:"current_language"`
@@ -161,6 +161,11 @@ func TestParseAcceptLanguage(t *testing.T) {
 		lang := parseAcceptLanguage("")
 		assert.Equal(t, "", lang)
 	})
+
+	t.Run("TestSupportedByWeight", func(t *testing.T) {
+		lang := parseAcceptLanguage("fr-FR,en-GB;q=0.9,ja;q=0.5", "zh-CN", "en-US", "ja-JP")
+		assert.Equal(t, "en-US", lang, "跳过不支持的语言，按基础语言匹配")
+	})
 }
 
 func TestNormalizeLanguage(t *testing.T) {
@@ -228,6 +233,22 @@ func TestI18nManager(t *testing.T) {
 	})
 }
 
+func TestI18nManager_GetPluralTranslation(t *testing.T) {
+	manager := &I18nManager{
+		config: &I18nConfig{DefaultLanguage: "zh-CN", FallbackToDefault: true},
+		translations: map[string]Translation{
+			"zh-CN": {"files": map[string]interface{}{"count": map[string]interface{}{"other": "%d 个文件"}}},
+			"en-US": {"files": map[string]interface{}{"count": map[string]interface{}{"one": "%d file", "other": "%d files"}}},
+		},
+	}
+
+	assert.Equal(t, "1 file", manager.GetPluralTranslation("en-US", "files.count", 1, 1))
+	assert.Equal(t, "3 files", manager.GetPluralTranslation("en-US", "files.count", 3, 3))
+	assert.Equal(t, "1 个文件", manager.GetPluralTranslation("zh-CN", "files.count", 1, 1))
+	assert.Equal(t, "2 个文件", manager.GetPluralTranslation("ja-JP", "files.count", 2, 2), "回退到默认语言")
+	assert.Equal(t, "missing", manager.GetPluralTranslation("en-US", "missing", 1))
+}
+
 func TestGetNestedValue(t *testing.T) {
 	manager := &I18nManager{}
 
@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/cache"
+	"cloudpan/internal/pkg/ratelimit"
+	"cloudpan/internal/pkg/utils"
+)
+
+// globalRateLimitScope 全局限流在限流键中的接口名
+const globalRateLimitScope = "global"
+
+// RateLimitRule 令牌桶限流规则
+type RateLimitRule struct {
+	RequestsPerMinute int // 每分钟补充的请求数，为0表示不限制
+	Burst             int // 突发容量，为0时等于每分钟请求数
+}
+
+// RouteRateLimit 单个接口的限流规则
+type RouteRateLimit struct {
+	Method string // 请求方法，为空匹配全部方法
+	Path   string // 路由模板，如 /api/v1/auth/login
+	RateLimitRule
+}
+
+// RateLimitOptions 限流中间件配置
+type RateLimitOptions struct {
+	IP     RateLimitRule    // 每个IP的限流，用于未登录请求，未配置用户限流时也用于登录请求
+	User   RateLimitRule    // 每个登录用户的限流
+	Routes []RouteRateLimit // 单个接口的限流，登录请求按用户计数，未登录请求按IP计数
+
+	// IdentifyUser 上下文中没有用户ID时识别请求的用户，
+	// 用于在认证中间件之前注册限流中间件，为nil时只按上下文中的用户ID识别
+	IdentifyUser func(c *gin.Context) (uint, bool)
+}
+
+// RateLimit 创建请求限流中间件
+//
+// 使用令牌桶算法，键由 cache.Keys.RateLimit 和 cache.Keys.UserRateLimit 构造。
+// 先检查接口限流，再检查用户或IP的全局限流，任一规则拒绝时返回429和Retry-After响应头。
+// 限流器出错(如Redis不可用)时放行请求，避免限流故障导致服务不可用
+//
+// 使用示例：
+//
+//	api.Use(middleware.RateLimit(ratelimit.NewRedisBucket(cache.RedisClient), middleware.RateLimitOptions{
+//		IP:     middleware.RateLimitRule{RequestsPerMinute: 60, Burst: 100},
+//		Routes: []middleware.RouteRateLimit{{Method: "POST", Path: "/api/v1/auth/login", RateLimitRule: middleware.RateLimitRule{RequestsPerMinute: 5}}},
+//	}, logger))
+func RateLimit(bucket ratelimit.Bucket, options RateLimitOptions, logger *zap.Logger) gin.HandlerFunc {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return func(c *gin.Context) {
+		// CORS预检请求不计数
+		if c.Request.Method == http.MethodOptions {
+			c.Next()
+			return
+		}
+
+		ip := c.ClientIP()
+		userID := contextUint(c, UserIDContextKey)
+		if userID == 0 && options.IdentifyUser != nil {
+			userID, _ = options.IdentifyUser(c)
+		}
+		keyFor := func(scope string) string {
+			if userID != 0 {
+				return cache.Keys.UserRateLimit(strconv.FormatUint(uint64(userID), 10), scope)
+			}
+			return cache.Keys.RateLimit(ip, scope)
+		}
+
+		if route := c.FullPath(); route != "" {
+			for _, rule := range options.Routes {
+				if rule.Path != route || (rule.Method != "" && rule.Method != c.Request.Method) {
+					continue
+				}
+				if !allowRequest(c, bucket, keyFor(c.Request.Method+" "+route), rule.RateLimitRule, logger) {
+					return
+				}
+			}
+		}
+
+		global := options.IP
+		if userID != 0 && options.User.RequestsPerMinute > 0 {
+			global = options.User
+		}
+		if !allowRequest(c, bucket, keyFor(globalRateLimitScope), global, logger) {
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// allowRequest 按规则消耗一个令牌，被拒绝时写入429响应并中止请求
+func allowRequest(c *gin.Context, bucket ratelimit.Bucket, key string, rule RateLimitRule, logger *zap.Logger) bool {
+	if rule.RequestsPerMinute <= 0 {
+		return true
+	}
+
+	result, err := bucket.Take(c.Request.Context(), key, rule.RequestsPerMinute, rule.Burst)
+	if err != nil {
+		logger.Warn("Rate limit check failed, request allowed",
+			zap.String("key", key),
+			zap.Error(err))
+		return true
+	}
+	if result.Allowed {
+		return true
+	}
+
+	logger.Info("Request rate limited",
+		zap.String("key", key),
+		zap.String("ip", c.ClientIP()),
+		zap.Duration("retry_after", result.RetryAfter))
+	c.Header("Retry-After", strconv.Itoa(retryAfterSeconds(result.RetryAfter)))
+	utils.ErrorWithMessage(c, utils.CodeTooManyRequests, "请求过于频繁，请稍后再试")
+	c.Abort()
+	return false
+}
+
+// retryAfterSeconds Retry-After响应头的秒数，向上取整且至少为1秒
+func retryAfterSeconds(wait time.Duration) int {
+	return max(int(math.Ceil(wait.Seconds())), 1)
+}
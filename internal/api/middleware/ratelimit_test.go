@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"cloudpan/internal/pkg/ratelimit"
+)
+
+// failingBucket 总是返回错误的令牌桶
+type failingBucket struct{}
+
+func (failingBucket) Take(context.Context, string, int, int) (*ratelimit.Result, error) {
+	return nil, errors.New("redis unavailable")
+}
+
+func newRateLimitRouter(bucket ratelimit.Bucket, options RateLimitOptions) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RateLimit(bucket, options, nil))
+	ok := func(c *gin.Context) { c.String(http.StatusOK, "ok") }
+	router.GET("/files", ok)
+	router.POST("/login", ok)
+	return router
+}
+
+func serveFrom(router *gin.Engine, method, path, ip, user string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.RemoteAddr = ip + ":1234"
+	if user != "" {
+		req.Header.Set("X-User", user)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimit_IP(t *testing.T) {
+	router := newRateLimitRouter(ratelimit.NewMemoryBucket(), RateLimitOptions{
+		IP: RateLimitRule{RequestsPerMinute: 1, Burst: 2},
+	})
+
+	assert.Equal(t, http.StatusOK, serveFrom(router, http.MethodGet, "/files", "10.0.0.1", "").Code)
+	assert.Equal(t, http.StatusOK, serveFrom(router, http.MethodGet, "/files", "10.0.0.1", "").Code)
+
+	w := serveFrom(router, http.MethodGet, "/files", "10.0.0.1", "")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+
+	// 其他IP和预检请求不受影响
+	assert.Equal(t, http.StatusOK, serveFrom(router, http.MethodGet, "/files", "10.0.0.2", "").Code)
+	assert.NotEqual(t, http.StatusTooManyRequests, serveFrom(router, http.MethodOptions, "/files", "10.0.0.1", "").Code)
+}
+
+func TestRateLimit_User(t *testing.T) {
+	router := newRateLimitRouter(ratelimit.NewMemoryBucket(), RateLimitOptions{
+		IP:   RateLimitRule{RequestsPerMinute: 1},
+		User: RateLimitRule{RequestsPerMinute: 2},
+		IdentifyUser: func(c *gin.Context) (uint, bool) {
+			if c.GetHeader("X-User") == "7" {
+				return 7, true
+			}
+			return 0, false
+		},
+	})
+
+	// 登录用户按用户计数，同一IP的多个请求不受IP限流影响
+	assert.Equal(t, http.StatusOK, serveFrom(router, http.MethodGet, "/files", "10.0.0.1", "7").Code)
+	assert.Equal(t, http.StatusOK, serveFrom(router, http.MethodGet, "/files", "10.0.0.2", "7").Code)
+	assert.Equal(t, http.StatusTooManyRequests, serveFrom(router, http.MethodGet, "/files", "10.0.0.3", "7").Code)
+
+	assert.Equal(t, http.StatusOK, serveFrom(router, http.MethodGet, "/files", "10.0.0.1", "").Code)
+	assert.Equal(t, http.StatusTooManyRequests, serveFrom(router, http.MethodGet, "/files", "10.0.0.1", "").Code)
+}
+
+func TestRateLimit_Route(t *testing.T) {
+	router := newRateLimitRouter(ratelimit.NewMemoryBucket(), RateLimitOptions{
+		IP:     RateLimitRule{RequestsPerMinute: 100},
+		Routes: []RouteRateLimit{{Method: http.MethodPost, Path: "/login", RateLimitRule: RateLimitRule{RequestsPerMinute: 1}}},
+	})
+
+	assert.Equal(t, http.StatusOK, serveFrom(router, http.MethodPost, "/login", "10.0.0.1", "").Code)
+	assert.Equal(t, http.StatusTooManyRequests, serveFrom(router, http.MethodPost, "/login", "10.0.0.1", "").Code)
+	assert.Equal(t, http.StatusOK, serveFrom(router, http.MethodGet, "/files", "10.0.0.1", "").Code)
+}
+
+func TestRateLimit_FailOpen(t *testing.T) {
+	router := newRateLimitRouter(failingBucket{}, RateLimitOptions{
+		IP: RateLimitRule{RequestsPerMinute: 1},
+	})
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, serveFrom(router, http.MethodGet, "/files", "10.0.0.1", "").Code)
+	}
+}
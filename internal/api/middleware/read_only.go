@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"cloudpan/internal/pkg/utils"
+)
+
+// ReadOnlyChecker 判断服务是否处于只读模式，由 readonly.Mode 实现
+type ReadOnlyChecker interface {
+	ReadOnly(ctx context.Context) bool
+}
+
+// ReadOnly 创建只读模式中间件
+//
+// 只读模式下GET、HEAD、OPTIONS请求照常处理，其余请求返回503和READ_ONLY业务码；
+// 路由模板(如 /api/v1/auth/、/api/v1/public/shares/:code/verify)以exempt中任一前缀开头的接口不受限制，
+// 用于保留登录、刷新令牌和分享提取码验证等不修改业务数据的接口
+func ReadOnly(checker ReadOnlyChecker, exempt ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+
+		route := c.FullPath()
+		for _, prefix := range exempt {
+			if strings.HasPrefix(route, prefix) {
+				c.Next()
+				return
+			}
+		}
+
+		if checker.ReadOnly(c.Request.Context()) {
+			utils.ErrorWithMessage(c, utils.CodeReadOnly, "服务维护中，暂时只能浏览和下载")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubReadOnlyChecker 固定状态的只读模式
+type stubReadOnlyChecker struct {
+	readOnly bool
+}
+
+func (s *stubReadOnlyChecker) ReadOnly(context.Context) bool { return s.readOnly }
+
+func TestReadOnly(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRouter := func(readOnly bool) *gin.Engine {
+		router := gin.New()
+		api := router.Group("/api/v1", ReadOnly(&stubReadOnlyChecker{readOnly: readOnly},
+			"/api/v1/auth/", "/api/v1/public/shares/:code/verify"))
+		ok := func(c *gin.Context) { c.String(http.StatusOK, "ok") }
+		api.GET("/files", ok)
+		api.POST("/files", ok)
+		api.DELETE("/files/:id", ok)
+		api.POST("/auth/login", ok)
+		api.POST("/public/shares/:code/verify", ok)
+		api.POST("/public/shares/:code/save", ok)
+		return router
+	}
+	serve := func(router *gin.Engine, method, path string) int {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w.Code
+	}
+
+	t.Run("read-only mode rejects writes", func(t *testing.T) {
+		router := newRouter(true)
+		assert.Equal(t, http.StatusOK, serve(router, http.MethodGet, "/api/v1/files"))
+		assert.Equal(t, http.StatusServiceUnavailable, serve(router, http.MethodPost, "/api/v1/files"))
+		assert.Equal(t, http.StatusServiceUnavailable, serve(router, http.MethodDelete, "/api/v1/files/1"))
+		assert.Equal(t, http.StatusServiceUnavailable, serve(router, http.MethodPost, "/api/v1/public/shares/abc/save"))
+
+		// 登录和分享提取码验证不受限制
+		assert.Equal(t, http.StatusOK, serve(router, http.MethodPost, "/api/v1/auth/login"))
+		assert.Equal(t, http.StatusOK, serve(router, http.MethodPost, "/api/v1/public/shares/abc/verify"))
+	})
+
+	t.Run("normal mode allows writes", func(t *testing.T) {
+		router := newRouter(false)
+		assert.Equal(t, http.StatusOK, serve(router, http.MethodPost, "/api/v1/files"))
+		assert.Equal(t, http.StatusOK, serve(router, http.MethodDelete, "/api/v1/files/1"))
+	})
+}
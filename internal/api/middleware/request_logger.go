@@ -2,7 +2,9 @@ package middleware
 
 import (
 	"bytes"
+	"context"
 	"io"
+	"strconv"
 	"strings"
 	"time"
 
@@ -11,6 +13,17 @@ import (
 	"go.uber.org/zap"
 
 	"cloudpan/internal/pkg/logger"
+	"cloudpan/internal/pkg/tracing"
+)
+
+const (
+	// RequestIDHeader 请求ID的请求头和响应头
+	RequestIDHeader = "X-Request-ID"
+	// RequestIDContextKey 请求ID在Gin上下文中的键
+	RequestIDContextKey = "request_id"
+
+	// maxRequestIDLength 客户端传入的请求ID最大长度，超出或含有其他字符时重新生成
+	maxRequestIDLength = 64
 )
 
 // RequestLogger HTTP请求日志中间件配置
@@ -49,6 +62,9 @@ func DefaultRequestLoggerConfig() RequestLoggerConfig {
 }
 
 // RequestLogger 创建请求日志中间件
+//
+// 请求完成后将请求ID、状态码、耗时、用户ID和IP等字段写入访问日志(logger.AccessLogger)，
+// 请求ID取自 RequestIDMiddleware 设置的上下文，因此应注册在其之后
 func RequestLogger(config ...RequestLoggerConfig) gin.HandlerFunc {
 	cfg := DefaultRequestLoggerConfig()
 	if len(config) > 0 {
@@ -74,8 +90,11 @@ func RequestLogger(config ...RequestLoggerConfig) gin.HandlerFunc {
 				return ""
 			}
 
-			// 生成请求ID
-			requestID := generateRequestID()
+			// 使用请求ID中间件设置的请求ID，未注册时生成新的请求ID
+			requestID, _ := param.Keys[RequestIDContextKey].(string)
+			if requestID == "" {
+				requestID = generateRequestID()
+			}
 
 			// 构建访问日志条目
 			entry := logger.AccessLogEntry{
@@ -89,14 +108,10 @@ func RequestLogger(config ...RequestLoggerConfig) gin.HandlerFunc {
 				IPAddress:    param.ClientIP,
 				UserAgent:    param.Request.UserAgent(),
 				RequestSize:  param.Request.ContentLength,
+				ResponseSize: int64(param.BodySize),
+				Referer:      param.Request.Referer(),
 				Protocol:     param.Request.Proto,
-			}
-
-			// 从上下文中获取用户ID（如果存在）
-			if userID := param.Keys["user_id"]; userID != nil {
-				if uid, ok := userID.(string); ok {
-					entry.UserID = uid
-				}
+				UserID:       formatUserID(param.Keys[UserIDContextKey]),
 			}
 
 			// 记录访问日志
@@ -153,12 +168,14 @@ func buildSkipPathsMap(skipPaths []string) map[string]bool {
 	return skipPathsMap
 }
 
-// setupRequestLogging 设置请求日志
+// setupRequestLogging 设置请求日志，沿用请求ID中间件设置的请求ID
 func setupRequestLogging(c *gin.Context) string {
-	// 生成请求ID并设置到上下文
+	if requestID := c.GetString(RequestIDContextKey); requestID != "" {
+		return requestID
+	}
 	requestID := generateRequestID()
-	c.Set("request_id", requestID)
-	c.Header("X-Request-ID", requestID)
+	c.Set(RequestIDContextKey, requestID)
+	c.Header(RequestIDHeader, requestID)
 	return requestID
 }
 
@@ -242,10 +259,13 @@ func buildLogFields(c *gin.Context, requestID string, duration time.Duration) []
 // addOptionalFields 添加可选字段
 func addOptionalFields(fields *[]zap.Field, c *gin.Context, requestBody string, responseWriter *bodyLogWriter) {
 	// 添加用户ID
-	if userID, exists := c.Get("user_id"); exists {
-		if uid, ok := userID.(string); ok {
-			*fields = append(*fields, zap.String("user_id", uid))
-		}
+	if uid := formatUserID(c.Value(UserIDContextKey)); uid != "" {
+		*fields = append(*fields, zap.String("user_id", uid))
+	}
+
+	// 添加追踪ID，便于从访问日志定位到对应的链路
+	if traceID := tracing.TraceID(c.Request.Context()); traceID != "" {
+		*fields = append(*fields, zap.String("trace_id", traceID))
 	}
 
 	// 添加请求体
@@ -289,21 +309,73 @@ func generateRequestID() string {
 	return strings.ReplaceAll(uuid.New().String(), "-", "")
 }
 
-// RequestIDMiddleware 请求ID中间件（轻量级版本）
+// RequestIDMiddleware 请求ID中间件
+//
+// 沿用客户端或上游网关传入的X-Request-ID，没有或格式无效时生成新的请求ID。
+// 请求ID写入Gin上下文(供响应体和错误日志使用)、响应头和请求的context.Context，
+// 后续可通过 ContextLogger 或 logger.WithContext 获取带请求ID的日志实例
 func RequestIDMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		requestID := c.GetHeader("X-Request-ID")
-		if requestID == "" {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID(requestID) {
 			requestID = generateRequestID()
 		}
 
-		c.Set("request_id", requestID)
-		c.Header("X-Request-ID", requestID)
+		c.Set(RequestIDContextKey, requestID)
+		c.Header(RequestIDHeader, requestID)
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), logger.RequestIDKey, requestID))
 
 		c.Next()
 	}
 }
 
+// ContextLogger 返回带请求ID和用户ID字段的日志实例，日志系统未初始化时返回空Logger
+func ContextLogger(c *gin.Context) *zap.Logger {
+	base := logger.Logger
+	if base == nil {
+		base = zap.NewNop()
+	}
+	if requestID := c.GetString(RequestIDContextKey); requestID != "" {
+		base = base.With(zap.String("request_id", requestID))
+	}
+	if uid := formatUserID(c.Value(UserIDContextKey)); uid != "" {
+		base = base.With(zap.String("user_id", uid))
+	}
+	return base
+}
+
+// validRequestID 检查外部传入的请求ID，只接受有限长度的字母、数字和 -_. 字符，避免日志注入
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range requestID {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// formatUserID 将上下文中的用户ID转为日志字段值，未登录时返回空字符串
+func formatUserID(value any) string {
+	switch v := value.(type) {
+	case uint64:
+		if v != 0 {
+			return strconv.FormatUint(v, 10)
+		}
+	case uint:
+		if v != 0 {
+			return strconv.FormatUint(uint64(v), 10)
+		}
+	case string:
+		return v
+	}
+	return ""
+}
+
 // UserIDMiddleware 用户ID中间件（需要在认证中间件之后使用）
 func UserIDMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -3,10 +3,15 @@ package middleware
 import (
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	"cloudpan/internal/pkg/logger"
 )
 
 func TestRequestLoggerBasic(t *testing.T) {
@@ -61,3 +66,77 @@ func TestRequestLoggerBasic(t *testing.T) {
 		})
 	}
 }
+
+func TestRequestIDMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.Use(RequestIDMiddleware())
+	r.GET("/id", func(c *gin.Context) {
+		// 请求ID同时写入请求的context，供 logger.WithContext 使用
+		assert.Equal(t, c.GetString(RequestIDContextKey), c.Request.Context().Value(logger.RequestIDKey))
+		c.String(http.StatusOK, c.GetString(RequestIDContextKey))
+	})
+
+	tests := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{name: "propagates incoming id", incoming: "gateway-123.abc_DEF", keep: true},
+		{name: "generates when missing", incoming: ""},
+		{name: "rejects unsafe characters", incoming: "abc\nforged=1"},
+		{name: "rejects overlong id", incoming: strings.Repeat("a", maxRequestIDLength+1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/id", nil)
+			if tt.incoming != "" {
+				req.Header.Set(RequestIDHeader, tt.incoming)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			requestID := w.Header().Get(RequestIDHeader)
+			assert.NotEmpty(t, requestID)
+			assert.Equal(t, requestID, w.Body.String())
+			if tt.keep {
+				assert.Equal(t, tt.incoming, requestID)
+			} else {
+				assert.NotEqual(t, tt.incoming, requestID)
+			}
+		})
+	}
+}
+
+func TestRequestLogger_AccessLog(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	core, logs := observer.New(zap.InfoLevel)
+	previous := logger.AccessLogger
+	logger.AccessLogger = zap.New(core)
+	defer func() { logger.AccessLogger = previous }()
+
+	r := gin.New()
+	r.Use(RequestIDMiddleware(), RequestLogger())
+	r.GET("/files", func(c *gin.Context) {
+		c.Set(UserIDContextKey, uint64(42))
+		c.String(http.StatusAccepted, "ok")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/files?page=2", nil)
+	req.Header.Set(RequestIDHeader, "req-1")
+	req.RemoteAddr = "10.0.0.9:1234"
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	entries := logs.All()
+	if assert.Len(t, entries, 1) {
+		fields := entries[0].ContextMap()
+		assert.Equal(t, "req-1", fields["request_id"])
+		assert.Equal(t, "42", fields["user_id"])
+		assert.Equal(t, "10.0.0.9", fields["ip_address"])
+		assert.Equal(t, int64(http.StatusAccepted), fields["status_code"])
+		assert.Equal(t, "page=2", fields["query"])
+		assert.Contains(t, fields, "response_time")
+	}
+}
@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"mime"
+	"net"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"cloudpan/internal/pkg/utils"
+)
+
+// 沙箱响应使用的内容安全策略
+const (
+	// sandboxBasePolicy 只允许加载同源图片、媒体和内联样式，禁止脚本、表单和插件
+	sandboxBasePolicy = "default-src 'none'; img-src 'self' data: blob:; media-src 'self'; style-src 'unsafe-inline'; font-src 'self' data:; base-uri 'none'; form-action 'none'"
+	// sandboxContentType 禁用HTML类内容时替换的内容类型
+	sandboxContentType = "application/octet-stream"
+)
+
+// htmlAdjacentTypes 浏览器会按文档解析、可能执行脚本的内容类型
+var htmlAdjacentTypes = map[string]bool{
+	"text/html":             true,
+	"application/xhtml+xml": true,
+	"image/svg+xml":         true,
+	"text/xml":              true,
+	"application/xml":       true,
+	"text/xsl":              true,
+	"application/xslt+xml":  true,
+}
+
+// SandboxOptions 用户内容沙箱配置
+type SandboxOptions struct {
+	Host          string   // 用户内容专用域名，设置后其他域名的请求返回404
+	ScriptSources []string // 代码高亮允许加载的脚本来源，为空时禁止执行脚本
+	DisableHTML   bool     // 禁止以HTML类内容类型返回，一律按二进制流返回
+}
+
+// Sandbox 创建用户内容沙箱中间件，用于预览、缩略图和下载等返回用户上传内容的接口
+//
+// 所有响应添加 X-Content-Type-Options: nosniff 和带 sandbox 指令的 Content-Security-Policy，
+// 即使浏览器把内容当作文档打开也运行在唯一的源中，无法读取主站的存储或以用户身份发起请求。
+// 配置了 ScriptSources 时非HTML类响应允许从这些来源加载代码高亮脚本，HTML、SVG、XML等内容始终禁止脚本；
+// DisableHTML 时这些内容类型在写出响应头时替换为 application/octet-stream，
+// 配置了 Host 时只响应该域名的请求，使用户内容与主站不同源
+func Sandbox(options SandboxOptions) gin.HandlerFunc {
+	host := strings.ToLower(options.Host)
+	scriptPolicy := sandboxPolicy(options.ScriptSources)
+	basePolicy := sandboxPolicy(nil)
+
+	return func(c *gin.Context) {
+		if host != "" && requestHost(c) != host {
+			utils.ErrorWithMessage(c, utils.CodeNotFound, "请通过用户内容域名访问")
+			c.Abort()
+			return
+		}
+
+		// 先按非HTML内容设置，没有响应体的响应(如304)不经过写出检查
+		c.Header("Content-Security-Policy", scriptPolicy)
+		c.Header("X-Content-Type-Options", "nosniff")
+		c.Writer = &sandboxWriter{
+			ResponseWriter: c.Writer,
+			disableHTML:    options.DisableHTML,
+			basePolicy:     basePolicy,
+		}
+		c.Next()
+	}
+}
+
+// sandboxPolicy 生成内容安全策略，sources 为空时不允许脚本
+func sandboxPolicy(sources []string) string {
+	if len(sources) == 0 {
+		return sandboxBasePolicy + "; sandbox"
+	}
+	return sandboxBasePolicy + "; script-src " + strings.Join(sources, " ") + "; sandbox allow-scripts"
+}
+
+// requestHost 请求的域名，去掉端口并转为小写
+func requestHost(c *gin.Context) string {
+	host := c.Request.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(host)
+}
+
+// IsHTMLAdjacent 判断内容类型是否会被浏览器按文档解析(HTML、XHTML、SVG、XML、XSL)
+func IsHTMLAdjacent(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.ToLower(strings.TrimSpace(contentType))
+	}
+	return htmlAdjacentTypes[mediaType]
+}
+
+// sandboxWriter 在写出响应头前按最终的内容类型调整安全响应头
+//
+// gin 的 WriteHeader 只记录状态码，响应头在首次写入时才写出，因此在写入前检查处理器设置的内容类型
+type sandboxWriter struct {
+	gin.ResponseWriter
+	disableHTML bool
+	basePolicy  string
+	applied     bool
+}
+
+// applyHeaders HTML类内容禁止脚本，禁用时替换内容类型，只执行一次
+func (w *sandboxWriter) applyHeaders() {
+	if w.applied || w.Written() {
+		return
+	}
+	w.applied = true
+
+	header := w.Header()
+	if !IsHTMLAdjacent(header.Get("Content-Type")) {
+		return
+	}
+	header.Set("Content-Security-Policy", w.basePolicy)
+	if w.disableHTML {
+		header.Set("Content-Type", sandboxContentType)
+	}
+}
+
+func (w *sandboxWriter) WriteHeaderNow() {
+	w.applyHeaders()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *sandboxWriter) Write(data []byte) (int, error) {
+	w.applyHeaders()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *sandboxWriter) WriteString(s string) (int, error) {
+	w.applyHeaders()
+	return w.ResponseWriter.WriteString(s)
+}
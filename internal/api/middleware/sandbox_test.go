@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSandbox(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRouter := func(options SandboxOptions) *gin.Engine {
+		router := gin.New()
+		router.GET("/files/:name", Sandbox(options), func(c *gin.Context) {
+			contentType := map[string]string{
+				"page.html": "text/html; charset=utf-8",
+				"logo.svg":  "image/svg+xml",
+				"main.go":   "text/plain; charset=utf-8",
+			}[c.Param("name")]
+			c.Header("Content-Type", contentType)
+			http.ServeContent(c.Writer, c.Request, c.Param("name"), time.Time{}, strings.NewReader("<script>alert(1)</script>"))
+		})
+		router.GET("/empty", Sandbox(options), func(c *gin.Context) { c.Status(http.StatusNotModified) })
+		return router
+	}
+	serve := func(router *gin.Engine, host, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Host = host
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("user content is sandboxed without scripts", func(t *testing.T) {
+		router := newRouter(SandboxOptions{})
+		for _, path := range []string{"/files/page.html", "/files/main.go", "/empty"} {
+			w := serve(router, "example.com", path)
+			policy := w.Header().Get("Content-Security-Policy")
+			assert.True(t, strings.HasSuffix(policy, "; sandbox"), path)
+			assert.NotContains(t, policy, "script-src", path)
+			assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"), path)
+		}
+		assert.Equal(t, "text/html; charset=utf-8", serve(router, "example.com", "/files/page.html").Header().Get("Content-Type"))
+	})
+
+	t.Run("highlight scripts are never allowed for HTML-like content", func(t *testing.T) {
+		router := newRouter(SandboxOptions{ScriptSources: []string{"https://cdn.example.com"}})
+
+		w := serve(router, "example.com", "/files/main.go")
+		assert.Contains(t, w.Header().Get("Content-Security-Policy"), "script-src https://cdn.example.com; sandbox allow-scripts")
+
+		w = serve(router, "example.com", "/files/logo.svg")
+		assert.NotContains(t, w.Header().Get("Content-Security-Policy"), "script-src")
+		assert.Equal(t, "image/svg+xml", w.Header().Get("Content-Type"))
+	})
+
+	t.Run("HTML-like content is served as binary when disabled", func(t *testing.T) {
+		router := newRouter(SandboxOptions{DisableHTML: true})
+		assert.Equal(t, "application/octet-stream", serve(router, "example.com", "/files/page.html").Header().Get("Content-Type"))
+		assert.Equal(t, "application/octet-stream", serve(router, "example.com", "/files/logo.svg").Header().Get("Content-Type"))
+		assert.Equal(t, "text/plain; charset=utf-8", serve(router, "example.com", "/files/main.go").Header().Get("Content-Type"))
+	})
+
+	t.Run("dedicated host rejects other origins", func(t *testing.T) {
+		router := newRouter(SandboxOptions{Host: "usercontent.example.com"})
+		assert.Equal(t, http.StatusNotFound, serve(router, "example.com", "/files/main.go").Code)
+		assert.Equal(t, http.StatusOK, serve(router, "UserContent.example.com:8443", "/files/main.go").Code)
+	})
+}
+
+func TestIsHTMLAdjacent(t *testing.T) {
+	assert.True(t, IsHTMLAdjacent("text/html"))
+	assert.True(t, IsHTMLAdjacent("Text/HTML; charset=utf-8"))
+	assert.True(t, IsHTMLAdjacent("image/svg+xml"))
+	assert.True(t, IsHTMLAdjacent("application/xhtml+xml"))
+	assert.False(t, IsHTMLAdjacent("text/plain"))
+	assert.False(t, IsHTMLAdjacent("image/jpeg"))
+	assert.False(t, IsHTMLAdjacent(""))
+}
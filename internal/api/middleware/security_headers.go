@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultContentSecurityPolicy 接口只返回JSON，不允许加载任何资源，也不允许被嵌入页面
+const defaultContentSecurityPolicy = "default-src 'none'; frame-ancestors 'none'"
+
+// SecurityHeadersOptions 安全响应头配置
+type SecurityHeadersOptions struct {
+	ContentSecurityPolicy string        // Content-Security-Policy，为空时不设置
+	FrameOptions          string        // X-Frame-Options，如DENY，为空时不设置(由CSP的frame-ancestors控制)
+	ReferrerPolicy        string        // Referrer-Policy，为空时不设置
+	HSTSMaxAge            time.Duration // Strict-Transport-Security的有效期，为0时不设置
+	HSTSIncludeSubdomains bool          // HSTS是否包含子域名
+	HSTSPreload           bool          // HSTS是否带preload指令
+}
+
+// DefaultSecurityHeadersOptions 默认安全响应头配置，不启用HSTS
+func DefaultSecurityHeadersOptions() SecurityHeadersOptions {
+	return SecurityHeadersOptions{
+		ContentSecurityPolicy: defaultContentSecurityPolicy,
+		ReferrerPolicy:        "no-referrer",
+	}
+}
+
+// SecurityHeaders 创建安全响应头中间件
+//
+// 所有响应添加 X-Content-Type-Options: nosniff 和配置的内容安全策略、Referrer-Policy、X-Frame-Options；
+// Strict-Transport-Security 只在HTTPS请求上设置，服务部署在反向代理之后时按 X-Forwarded-Proto 判断。
+// 之后注册的中间件(如用户内容沙箱)可以覆盖这些响应头
+func SecurityHeaders(options SecurityHeadersOptions) gin.HandlerFunc {
+	hsts := hstsValue(options)
+
+	return func(c *gin.Context) {
+		header := c.Writer.Header()
+		header.Set("X-Content-Type-Options", "nosniff")
+		if options.ContentSecurityPolicy != "" {
+			header.Set("Content-Security-Policy", options.ContentSecurityPolicy)
+		}
+		if options.FrameOptions != "" {
+			header.Set("X-Frame-Options", options.FrameOptions)
+		}
+		if options.ReferrerPolicy != "" {
+			header.Set("Referrer-Policy", options.ReferrerPolicy)
+		}
+		if hsts != "" && isHTTPS(c) {
+			header.Set("Strict-Transport-Security", hsts)
+		}
+		c.Next()
+	}
+}
+
+// hstsValue 生成Strict-Transport-Security的值，未配置有效期时返回空
+func hstsValue(options SecurityHeadersOptions) string {
+	if options.HSTSMaxAge <= 0 {
+		return ""
+	}
+	value := "max-age=" + strconv.FormatInt(int64(options.HSTSMaxAge/time.Second), 10)
+	if options.HSTSIncludeSubdomains {
+		value += "; includeSubDomains"
+	}
+	if options.HSTSPreload {
+		value += "; preload"
+	}
+	return value
+}
+
+// isHTTPS 请求是否通过HTTPS到达，TLS在反向代理终止时按 X-Forwarded-Proto 判断
+func isHTTPS(c *gin.Context) bool {
+	if c.Request.TLS != nil {
+		return true
+	}
+	proto, _, _ := strings.Cut(c.GetHeader("X-Forwarded-Proto"), ",")
+	return strings.EqualFold(strings.TrimSpace(proto), "https")
+}
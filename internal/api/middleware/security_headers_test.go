@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRouter := func(options SecurityHeadersOptions) *gin.Engine {
+		router := gin.New()
+		router.Use(SecurityHeaders(options))
+		router.GET("/api", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{}) })
+		router.GET("/preview", Sandbox(SandboxOptions{}), func(c *gin.Context) { c.String(http.StatusOK, "x") })
+		return router
+	}
+	serve := func(router *gin.Engine, path string, prepare func(req *http.Request)) http.Header {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if prepare != nil {
+			prepare(req)
+		}
+		router.ServeHTTP(w, req)
+		return w.Header()
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		header := serve(newRouter(DefaultSecurityHeadersOptions()), "/api", nil)
+		assert.Equal(t, "nosniff", header.Get("X-Content-Type-Options"))
+		assert.Equal(t, defaultContentSecurityPolicy, header.Get("Content-Security-Policy"))
+		assert.Equal(t, "no-referrer", header.Get("Referrer-Policy"))
+		assert.Empty(t, header.Get("X-Frame-Options"))
+		assert.Empty(t, header.Get("Strict-Transport-Security"), "未配置有效期时不设置HSTS")
+	})
+
+	t.Run("hsts only over https", func(t *testing.T) {
+		options := DefaultSecurityHeadersOptions()
+		options.HSTSMaxAge = 365 * 24 * time.Hour
+		options.HSTSIncludeSubdomains = true
+		options.FrameOptions = "DENY"
+		router := newRouter(options)
+
+		header := serve(router, "/api", nil)
+		assert.Empty(t, header.Get("Strict-Transport-Security"), "HTTP请求不设置HSTS")
+		assert.Equal(t, "DENY", header.Get("X-Frame-Options"))
+
+		header = serve(router, "/api", func(req *http.Request) { req.TLS = &tls.ConnectionState{} })
+		assert.Equal(t, "max-age=31536000; includeSubDomains", header.Get("Strict-Transport-Security"))
+
+		header = serve(router, "/api", func(req *http.Request) { req.Header.Set("X-Forwarded-Proto", "HTTPS, http") })
+		assert.Equal(t, "max-age=31536000; includeSubDomains", header.Get("Strict-Transport-Security"), "反向代理终止TLS")
+	})
+
+	t.Run("sandbox overrides policy", func(t *testing.T) {
+		header := serve(newRouter(DefaultSecurityHeadersOptions()), "/preview", nil)
+		assert.Contains(t, header.Get("Content-Security-Policy"), "sandbox")
+		assert.Len(t, header.Values("Content-Security-Policy"), 1)
+	})
+}
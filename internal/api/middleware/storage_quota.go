@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/utils"
+)
+
+// StorageQuotaChecker 检查用户剩余存储空间，由 user.StorageQuotaService 实现
+type StorageQuotaChecker interface {
+	Check(ctx context.Context, userID uint, size int64) error
+}
+
+// quotaDetailer 携带配额使用情况的错误，响应时写入data字段
+type quotaDetailer interface {
+	QuotaDetails() interface{}
+}
+
+// StorageQuota 创建存储配额检查中间件
+//
+// 注册在认证中间件之后的上传接口上，按请求体大小(至少1字节)检查剩余空间，空间不足时直接拒绝，
+// data中返回当前配额和用量。这里只做快速拒绝，精确的预留由上传服务按声明的文件大小完成；
+// 检查本身出错时放行，交给上传服务处理
+func StorageQuota(checker StorageQuotaChecker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := contextUint(c, UserIDContextKey)
+		if userID == 0 {
+			c.Next()
+			return
+		}
+
+		err := checker.Check(c.Request.Context(), userID, max(c.Request.ContentLength, 1))
+		if err != nil && errors.Is(err, pkgErrors.ErrQuotaExceeded) {
+			var detailer quotaDetailer
+			if errors.As(err, &detailer) {
+				utils.ErrorWithData(c, utils.CodeQuotaExceeded, err.Error(), detailer.QuotaDetails())
+			} else {
+				utils.ErrorWithMessage(c, utils.CodeQuotaExceeded, err.Error())
+			}
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/utils"
+)
+
+// quotaTestError 携带用量的配额错误
+type quotaTestError struct {
+	available int64
+}
+
+func (e *quotaTestError) Error() string             { return "存储空间不足" }
+func (e *quotaTestError) Unwrap() error             { return pkgErrors.ErrQuotaExceeded }
+func (e *quotaTestError) QuotaDetails() interface{} { return gin.H{"available": e.available} }
+
+// stubQuotaChecker 剩余空间固定的配额检查
+type stubQuotaChecker struct {
+	available int64
+	err       error
+	sizes     []int64
+}
+
+func (s *stubQuotaChecker) Check(_ context.Context, _ uint, size int64) error {
+	s.sizes = append(s.sizes, size)
+	if s.err != nil {
+		return s.err
+	}
+	if size > s.available {
+		return &quotaTestError{available: s.available}
+	}
+	return nil
+}
+
+func TestStorageQuota(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	serve := func(checker *stubQuotaChecker, body string, authed bool) *httptest.ResponseRecorder {
+		router := gin.New()
+		router.POST("/upload", func(c *gin.Context) {
+			if authed {
+				c.Set(UserIDContextKey, uint64(7))
+			}
+		}, StorageQuota(checker), func(c *gin.Context) {
+			c.String(http.StatusOK, "ok")
+		})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(body)))
+		return w
+	}
+
+	t.Run("空间不足时拒绝并返回用量", func(t *testing.T) {
+		checker := &stubQuotaChecker{available: 3}
+		w := serve(checker, "hello", true)
+
+		require.Equal(t, http.StatusForbidden, w.Code)
+		var resp utils.Response
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, utils.CodeQuotaExceeded, resp.Code)
+		assert.Equal(t, float64(3), resp.Data.(map[string]interface{})["available"])
+		assert.Equal(t, []int64{5}, checker.sizes, "按请求体大小检查")
+	})
+
+	t.Run("空请求体至少需要1字节", func(t *testing.T) {
+		checker := &stubQuotaChecker{available: 0}
+		w := serve(checker, "", true)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Equal(t, []int64{1}, checker.sizes)
+	})
+
+	t.Run("空间充足或检查出错时放行", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, serve(&stubQuotaChecker{available: 10}, "hello", true).Code)
+		assert.Equal(t, http.StatusOK, serve(&stubQuotaChecker{err: errors.New("db down")}, "hello", true).Code)
+	})
+
+	t.Run("未登录不检查", func(t *testing.T) {
+		checker := &stubQuotaChecker{}
+		assert.Equal(t, http.StatusOK, serve(checker, "hello", false).Code)
+		assert.Empty(t, checker.sizes)
+	})
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"cloudpan/internal/pkg/tracing"
+)
+
+// TraceIDHeader 已采样请求的追踪ID响应头，便于按响应定位链路
+const TraceIDHeader = "X-Trace-ID"
+
+// Tracing 请求追踪中间件
+//
+// 沿用上游通过traceparent请求头传入的链路，为每个请求创建服务端span并写入请求的context.Context，
+// 处理器将 c.Request.Context() 传给服务后，服务、数据库和Redis调用的span都挂在该span下。
+// span名称使用路由模板(如 POST /api/v1/files/uploads/:id/chunks/:index)，避免路径参数导致名称过多；
+// 状态码为5xx时将span标记为失败。须在请求ID中间件之后注册
+func Tracing() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+
+		route := c.FullPath()
+		name := c.Request.Method + " " + route
+		if route == "" {
+			name = c.Request.Method + " unmatched"
+		}
+		ctx, span := tracing.Tracer().Start(ctx, name,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(c.Request.Method),
+				semconv.HTTPRoute(route),
+				semconv.URLPath(c.Request.URL.Path),
+				semconv.ClientAddress(c.ClientIP()),
+			),
+		)
+		defer span.End()
+
+		if requestID := c.GetString(RequestIDContextKey); requestID != "" {
+			span.SetAttributes(attribute.String("request.id", requestID))
+		}
+		if span.SpanContext().IsSampled() {
+			c.Header(TraceIDHeader, span.SpanContext().TraceID().String())
+		}
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if uid := formatUserID(c.Value(UserIDContextKey)); uid != "" {
+			span.SetAttributes(semconv.EnduserID(uid))
+		}
+		if len(c.Errors) > 0 {
+			span.RecordError(c.Errors.Last().Err)
+		}
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
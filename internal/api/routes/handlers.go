@@ -7,8 +7,12 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"cloudpan/internal/api/middleware"
+	"cloudpan/internal/pkg/buildinfo"
 	"cloudpan/internal/pkg/config"
 	"cloudpan/internal/pkg/database"
+	"cloudpan/internal/pkg/storage"
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/service/readonly"
 )
 
 // HealthCheckHandler 基础健康检查处理器
@@ -53,21 +57,77 @@ func DatabaseHealthHandler(c *gin.Context) {
 	c.JSON(statusCode, response)
 }
 
+// ReadinessHandler 就绪检查处理器
+//
+// 数据库连接全部健康时返回200，否则返回503；只读模式下实例仍可提供浏览和下载，视为就绪，
+// mode字段返回read_only或read_write，read_only_source返回只读模式的开启来源；
+// 启用存储健康检查时storage字段返回各存储后端的熔断状态，主存储和副本全部熔断时返回503
+func ReadinessHandler(c *gin.Context) {
+	status := database.Status()
+	statusCode := http.StatusOK
+	ready := "ready"
+	for _, dbStatus := range status {
+		if dbInfo, ok := dbStatus.(map[string]interface{}); ok && dbInfo["status"] != "healthy" {
+			statusCode = http.StatusServiceUnavailable
+			ready = "not_ready"
+			break
+		}
+	}
+
+	var mode readonly.Status
+	if readOnly := readonly.Default(); readOnly != nil {
+		mode = readOnly.Status(c.Request.Context())
+	}
+	response := gin.H{
+		"status":    ready,
+		"mode":      "read_write",
+		"read_only": mode.ReadOnly,
+		"databases": status,
+		"timestamp": time.Now().Unix(),
+	}
+	if mode.ReadOnly {
+		response["mode"] = "read_only"
+		response["read_only_source"] = mode.Source
+	}
+	if monitor := storage.DefaultHealthMonitor(); monitor != nil {
+		response["storage"] = monitor.Status()
+		if !monitor.Readable() {
+			statusCode = http.StatusServiceUnavailable
+			response["status"] = "not_ready"
+		}
+	}
+
+	c.JSON(statusCode, response)
+}
+
+// BuildInfoHandler 构建信息处理器
+//
+// 返回当前实例的版本、Git提交、构建时间和Go版本，用于将线上行为与具体发布对应
+func BuildInfoHandler(c *gin.Context) {
+	utils.Success(c, buildinfo.Get())
+}
+
 // SystemStatsHandler 系统统计信息处理器
+//
+// 配置信息取自脱敏后的生效配置，完整配置通过管理员配置查询接口获取
 func SystemStatsHandler(c *gin.Context) {
+	effective := config.Effective(config.AppConfig)
+	app := effective.Section("app")
+	server := effective.Section("server")
 	stats := gin.H{
 		"application": gin.H{
-			"name":    config.AppConfig.App.Name,
-			"version": config.AppConfig.App.Version,
-			"env":     config.AppConfig.App.Env,
-			"debug":   config.AppConfig.App.Debug,
+			"name":    app["name"],
+			"version": app["version"],
+			"env":     app["env"],
+			"debug":   app["debug"],
+			"build":   buildinfo.Get(),
 		},
 		"server": gin.H{
-			"host":             config.AppConfig.Server.Host,
-			"port":             config.AppConfig.Server.Port,
-			"read_timeout":     config.AppConfig.Server.ReadTimeout.String(),
-			"write_timeout":    config.AppConfig.Server.WriteTimeout.String(),
-			"max_header_bytes": config.AppConfig.Server.MaxHeaderBytes,
+			"host":             server["host"],
+			"port":             server["port"],
+			"read_timeout":     server["read_timeout"],
+			"write_timeout":    server["write_timeout"],
+			"max_header_bytes": server["max_header_bytes"],
 		},
 		"database":    database.Status(),
 		"timestamp":   time.Now().Unix(),
@@ -84,3 +144,18 @@ func SystemStatsHandler(c *gin.Context) {
 
 	c.JSON(http.StatusOK, response)
 }
+
+// EffectiveConfigHandler 生效配置查询处理器
+//
+// @Summary 查询生效配置
+// @Description 返回当前实例生效的完整配置及每个配置项的来源(默认值、配置文件或环境变量)，密码、密钥等敏感配置项已掩码
+// @Tags 系统
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response{data=config.EffectiveConfig} "生效配置"
+// @Failure 401 {object} utils.Response "未认证"
+// @Failure 403 {object} utils.Response "需要管理员权限"
+// @Router /api/v1/admin/system/config [get]
+func EffectiveConfigHandler(c *gin.Context) {
+	utils.Success(c, config.Effective(config.AppConfig))
+}
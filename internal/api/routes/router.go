@@ -1,14 +1,51 @@
 package routes
 
 import (
+	"context"
+	"net/http/pprof"
+	"slices"
+	"strings"
+	"sync"
+
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 
 	"cloudpan/internal/api/handlers"
 	"cloudpan/internal/api/middleware"
+	"cloudpan/internal/pkg/antivirus"
+	"cloudpan/internal/pkg/cache"
+	"cloudpan/internal/pkg/captcha"
+	"cloudpan/internal/pkg/clock"
 	"cloudpan/internal/pkg/config"
+	"cloudpan/internal/pkg/database"
+	"cloudpan/internal/pkg/email"
+	"cloudpan/internal/pkg/idgen"
 	"cloudpan/internal/pkg/logger"
+	"cloudpan/internal/pkg/moderation"
+	"cloudpan/internal/pkg/ratelimit"
+	"cloudpan/internal/pkg/storage"
+	"cloudpan/internal/pkg/tracing"
+	filerepo "cloudpan/internal/repository/file"
+	notificationrepo "cloudpan/internal/repository/notification"
+	systemrepo "cloudpan/internal/repository/system"
+	teamrepo "cloudpan/internal/repository/team"
+	userrepo "cloudpan/internal/repository/user"
+	"cloudpan/internal/service/acl"
+	auditsvc "cloudpan/internal/service/audit"
+	"cloudpan/internal/service/automation"
+	featureflagsvc "cloudpan/internal/service/featureflag"
+	filesvc "cloudpan/internal/service/file"
+	"cloudpan/internal/service/jobs"
+	limitssvc "cloudpan/internal/service/limits"
+	"cloudpan/internal/service/maintenance"
+	"cloudpan/internal/service/readonly"
+	sharesvc "cloudpan/internal/service/share"
+	"cloudpan/internal/service/sso"
+	teamsvc "cloudpan/internal/service/team"
+	usagesvc "cloudpan/internal/service/usage"
 	"cloudpan/internal/service/user"
+	"cloudpan/internal/service/verification"
+	"cloudpan/internal/service/warmup"
 )
 
 // getLogger 获取logger实例，如果logger没有初始化则使用默认的nop logger
@@ -33,35 +70,45 @@ func SetupRouter() *gin.Engine {
 	// 添加API路由
 	setupAPIRoutes(r)
 
+	// 添加性能分析路由
+	setupPProfRoutes(r)
+
 	return r
 }
 
 // setupMiddleware 设置中间件
 func setupMiddleware(r *gin.Engine) {
-	// 基础中间件
-	r.Use(gin.Logger())
+	// 基础中间件，控制台请求日志只在调试模式输出，访问日志由请求日志中间件写入文件
+	if config.AppConfig.App.Debug {
+		r.Use(gin.Logger())
+	}
 	r.Use(gin.Recovery())
 
-	// 请求ID中间件
+	// 请求ID中间件，须在请求日志和错误处理之前注册
 	r.Use(middleware.RequestIDMiddleware())
 
+	// 请求追踪中间件，启用追踪时注册，须在请求日志之前注册以便访问日志记录追踪ID
+	if tracing.Enabled() {
+		r.Use(middleware.Tracing())
+	}
+
 	// 请求日志中间件
 	r.Use(middleware.RequestLogger())
 
 	// 错误处理中间件
 	r.Use(middleware.ErrorHandler())
 
+	// API用量统计中间件，未启用用量统计时不注册
+	if usageService := usagesvc.Default(); usageService != nil {
+		r.Use(middleware.APIUsage(usageService))
+	}
+
 	// CORS中间件
-	if config.AppConfig.App.Debug {
-		// 开发环境允许所有源
-		r.Use(middleware.CORSMiddleware())
-	} else {
-		// 生产环境限制源
-		allowedOrigins := []string{
-			"https://cloudpan.hxlos.com",
-			"https://www.hxlos.com",
-		}
-		r.Use(middleware.ProductionCORS(allowedOrigins))
+	r.Use(middleware.CORS(newCORSOptions()))
+
+	// 安全响应头中间件，须在用户内容沙箱之前注册以便沙箱覆盖内容安全策略
+	if headersConfig := config.AppConfig.Security.Headers; headersConfig.Enabled {
+		r.Use(middleware.SecurityHeaders(newSecurityHeadersOptions(headersConfig)))
 	}
 
 	// API版本管理中间件
@@ -73,18 +120,123 @@ func setupMiddleware(r *gin.Engine) {
 	r.Use(middleware.I18nMiddleware(i18nConfig))
 }
 
+// newCORSOptions 按配置创建CORS选项，未配置的项使用默认值
+//
+// 未配置允许的源时，调试模式允许所有源，生产环境只允许官方域名
+func newCORSOptions() *middleware.CORSOptions {
+	corsConfig := config.AppConfig.Security.CORS
+	var opts *middleware.CORSOptions
+	switch {
+	case len(corsConfig.AllowOrigins) > 0:
+		opts = middleware.ProductionCORSOptions(corsConfig.AllowOrigins)
+	case config.AppConfig.App.Debug:
+		opts = middleware.DefaultCORSOptions()
+	default:
+		opts = middleware.ProductionCORSOptions([]string{
+			"https://cloudpan.hxlos.com",
+			"https://www.hxlos.com",
+		})
+	}
+
+	if len(corsConfig.AllowMethods) > 0 {
+		opts.AllowedMethods = corsConfig.AllowMethods
+	}
+	if len(corsConfig.AllowHeaders) > 0 {
+		opts.AllowedHeaders = corsConfig.AllowHeaders
+	}
+	if len(corsConfig.ExposeHeaders) > 0 {
+		opts.ExposedHeaders = corsConfig.ExposeHeaders
+	}
+	if corsConfig.AllowCredentials != nil {
+		opts.AllowCredentials = *corsConfig.AllowCredentials
+	}
+	if corsConfig.MaxAge > 0 {
+		opts.MaxAge = corsConfig.MaxAge
+	}
+	return opts
+}
+
+// newSecurityHeadersOptions 按配置创建安全响应头选项，未配置的策略使用默认值
+func newSecurityHeadersOptions(headersConfig config.HeadersConfig) middleware.SecurityHeadersOptions {
+	opts := middleware.DefaultSecurityHeadersOptions()
+	if headersConfig.ContentSecurityPolicy != "" {
+		opts.ContentSecurityPolicy = headersConfig.ContentSecurityPolicy
+	}
+	if headersConfig.ReferrerPolicy != "" {
+		opts.ReferrerPolicy = headersConfig.ReferrerPolicy
+	}
+	opts.FrameOptions = headersConfig.FrameOptions
+	opts.HSTSMaxAge = headersConfig.HSTSMaxAge
+	opts.HSTSIncludeSubdomains = headersConfig.HSTSIncludeSubdomains
+	opts.HSTSPreload = headersConfig.HSTSPreload
+	return opts
+}
+
 // setupHealthRoutes 设置健康检查路由
 func setupHealthRoutes(r *gin.Engine) {
 	r.GET("/health", HealthCheckHandler)
 	r.GET("/health/database", DatabaseHealthHandler)
+	r.GET("/readyz", ReadinessHandler)
+}
+
+// setupPProfRoutes 设置性能分析路由，仅在配置开启时注册且只允许管理员访问
+//
+// 用于在生产环境排查内存和goroutine泄漏，例如：
+//
+//	go tool pprof -http=:8081 -H "Authorization: Bearer $TOKEN" https://host/debug/pprof/heap
+func setupPProfRoutes(r *gin.Engine) {
+	pprofConfig := config.AppConfig.Monitoring.PProf
+	if !pprofConfig.Enabled {
+		return
+	}
+
+	authMiddleware, err := middleware.NewAuthMiddleware(config.AppConfig.JWT.Secret, getLogger())
+	if err != nil {
+		getLogger().Error("Failed to create auth middleware", zap.Error(err))
+		return
+	}
+
+	path := pprofConfig.Path
+	if path == "" {
+		path = "/debug/pprof"
+	}
+
+	debug := r.Group(path, authMiddleware.RequireAuth(), authMiddleware.RequireRole("admin"))
+	{
+		debug.GET("/", gin.WrapF(pprof.Index))
+		debug.GET("/cmdline", gin.WrapF(pprof.Cmdline))
+		debug.GET("/profile", gin.WrapF(pprof.Profile))
+		debug.GET("/symbol", gin.WrapF(pprof.Symbol))
+		debug.POST("/symbol", gin.WrapF(pprof.Symbol))
+		debug.GET("/trace", gin.WrapF(pprof.Trace))
+		// heap、goroutine、allocs、block、mutex、threadcreate 等命名profile
+		debug.GET("/:name", func(c *gin.Context) {
+			pprof.Handler(c.Param("name")).ServeHTTP(c.Writer, c.Request)
+		})
+	}
+
+	getLogger().Warn("pprof endpoints enabled", zap.String("path", path))
 }
 
 // setupAPIRoutes 设置API路由
 func setupAPIRoutes(r *gin.Engine) {
+	configureAPIKeyAuth()
+
+	// 限流只作用于API路由，健康检查和性能分析路由不受限制
+	api := r.Group("/api")
+	if rateLimit := newRateLimitMiddleware(); rateLimit != nil {
+		api.Use(rateLimit)
+	}
+	// 只读模式下拒绝修改数据的请求，认证接口和分享提取码验证除外
+	if readOnly := readonly.Default(); readOnly != nil {
+		api.Use(middleware.ReadOnly(readOnly, "/api/v1/auth/", "/api/v1/public/shares/:code/verify"))
+	}
+
 	// API v1 路由组
-	v1 := r.Group("/api/v1")
+	v1 := api.Group("/v1")
 	{
 		// 系统信息
+		v1.GET("/version", BuildInfoHandler)
 		v1.GET("/system/stats", SystemStatsHandler)
 		v1.GET("/system/version", middleware.VersionInfoHandler())
 		v1.GET("/system/language", middleware.LanguageInfoHandler())
@@ -92,12 +244,33 @@ func setupAPIRoutes(r *gin.Engine) {
 		// 预留其他业务路由
 		setupUserRoutes(v1)
 		setupFileRoutes(v1)
+		setupFolderRuleRoutes(v1)
+		setupFileTransferRoutes(v1)
+		setupShareRoutes(v1)
+		setupLimitsRoutes(v1)
+		setupFeatureRoutes(v1)
+		setupAdminCacheRoutes(v1)
+		setupAdminMaintenanceRoutes(v1)
+		setupStorageHealthRoutes(v1)
+		setupAdminJobRoutes(v1)
+		setupAdminUploadRoutes(v1)
+		setupAdminUserRoutes(v1)
+		setupAdminAuditRoutes(v1)
+		setupAdminSecurityAuditRoutes(v1)
+		setupAdminSSORoutes(v1)
+		setupAdminEmailRoutes(v1)
+		setupAdminFileGrantRoutes(v1)
+		setupAdminFileTransferRoutes(v1)
+		setupAdminRetainedContentRoutes(v1)
+		setupAdminFolderLimitRoutes(v1)
+		setupSCIMRoutes(v1)
+		setupAdminSystemRoutes(v1)
 		setupTeamRoutes(v1)
 		setupMessageRoutes(v1)
 	}
 
 	// API v2 路由组（预留）
-	v2 := r.Group("/api/v2")
+	v2 := api.Group("/v2")
 	{
 		v2.GET("/system/stats", SystemStatsHandler)
 		v2.GET("/system/version", middleware.VersionInfoHandler())
@@ -105,6 +278,94 @@ func setupAPIRoutes(r *gin.Engine) {
 	}
 }
 
+var (
+	requestBucketOnce sync.Once
+	requestBucket     ratelimit.Bucket
+)
+
+// requestRateLimitBucket 请求限流和API密钥限流共用的令牌桶
+//
+// 多实例部署时通过Redis共享令牌桶，Redis未初始化时退化为进程内令牌桶，只注册一次过期清理任务
+func requestRateLimitBucket() ratelimit.Bucket {
+	requestBucketOnce.Do(func() {
+		if cache.RedisClient != nil {
+			requestBucket = ratelimit.NewRedisBucket(cache.RedisClient)
+			return
+		}
+		memoryBucket := ratelimit.NewMemoryBucket()
+		requestBucket = memoryBucket
+		if scheduler := maintenance.Default(); scheduler != nil {
+			task := maintenance.PruneTask(maintenance.TaskRequestLimits, memoryBucket)
+			if err := scheduler.Register(task); err != nil {
+				getLogger().Warn("Failed to register request rate limit cleanup", zap.Error(err))
+			}
+		}
+	})
+	return requestBucket
+}
+
+// newRateLimitMiddleware 按配置创建请求限流中间件，未启用限流时返回nil
+//
+// 限流中间件在认证中间件之前运行，通过访问令牌识别登录用户
+func newRateLimitMiddleware() gin.HandlerFunc {
+	rateConfig := config.AppConfig.Security.RateLimit
+	if !rateConfig.Enabled {
+		return nil
+	}
+
+	bucket := requestRateLimitBucket()
+	options := middleware.RateLimitOptions{
+		IP:   middleware.RateLimitRule{RequestsPerMinute: rateConfig.RequestsPerMinute, Burst: rateConfig.Burst},
+		User: middleware.RateLimitRule{RequestsPerMinute: rateConfig.UserRequestsPerMinute, Burst: rateConfig.UserBurst},
+	}
+	for _, route := range rateConfig.Routes {
+		options.Routes = append(options.Routes, middleware.RouteRateLimit{
+			Method:        strings.ToUpper(route.Method),
+			Path:          route.Path,
+			RateLimitRule: middleware.RateLimitRule{RequestsPerMinute: route.RequestsPerMinute, Burst: route.Burst},
+		})
+	}
+	if authMiddleware, err := middleware.NewAuthMiddleware(config.AppConfig.JWT.Secret, getLogger()); err == nil {
+		options.IdentifyUser = authMiddleware.IdentifyUser
+	} else {
+		getLogger().Warn("Rate limiting by user disabled: invalid JWT configuration", zap.Error(err))
+	}
+
+	return middleware.RateLimit(bucket, options, getLogger())
+}
+
+// configureAPIKeyAuth 按配置设置认证中间件使用的API密钥认证，未启用时不接受API密钥
+//
+// API密钥在认证中间件中按 cache.Keys.APIRateLimit 单独限流，不受 security.rate_limit.enabled 影响
+func configureAPIKeyAuth() {
+	service := newAPIKeyService()
+	if service == nil {
+		middleware.SetDefaultAPIKeyAuth(nil)
+		return
+	}
+	keys := &middleware.APIKeyAuth{Authenticator: service}
+	if keyConfig := config.AppConfig.Security.APIKeys; keyConfig.RequestsPerMinute > 0 {
+		keys.Bucket = requestRateLimitBucket()
+		keys.RateLimit = middleware.RateLimitRule{RequestsPerMinute: keyConfig.RequestsPerMinute, Burst: keyConfig.Burst}
+	}
+	middleware.SetDefaultAPIKeyAuth(keys)
+}
+
+// newAPIKeyService 创建用户API密钥服务，未启用API密钥时返回nil
+func newAPIKeyService() user.APIKeyService {
+	keyConfig := config.AppConfig.Security.APIKeys
+	if !keyConfig.Enabled {
+		return nil
+	}
+	db := database.GetDB()
+	return user.NewAPIKeyService(
+		userrepo.NewAPIKeyRepository(db),
+		userrepo.NewUserRepository(db),
+		user.APIKeyOptionsFromConfig(keyConfig),
+		getLogger(),
+	)
+}
+
 // setupUserRoutes 设置用户相关路由
 func setupUserRoutes(rg *gin.RouterGroup) {
 	// 初始化登录处理器
@@ -119,6 +380,18 @@ func setupUserRoutes(rg *gin.RouterGroup) {
 		getLogger().Error("Failed to create login handler", zap.Error(err))
 		return
 	}
+	loginHandler.SetSecurityAuditService(auditsvc.DefaultSecurity())
+	// 登录时记录最后登录时间并恢复长期未登录策略的处置；未启用策略时同样记录，启用后据此判断
+	inactivityService := newInactivityService()
+	loginHandler.SetInactivityService(inactivityService)
+	if scheduler := maintenance.Default(); scheduler != nil && config.AppConfig.User.Inactivity.Enabled {
+		task := maintenance.Task{Name: maintenance.TaskInactiveAccounts, Run: func(ctx context.Context) (int64, error) {
+			return inactivityService.Enforce(ctx, scheduler.BatchSize())
+		}}
+		if err := scheduler.Register(task); err != nil {
+			getLogger().Warn("Failed to register inactive account policy", zap.Error(err))
+		}
+	}
 
 	// 认证相关路由（不需要认证）
 	auth := rg.Group("/auth")
@@ -133,6 +406,8 @@ func setupUserRoutes(rg *gin.RouterGroup) {
 		if loginHandler != nil {
 			auth.POST("/login", loginHandler.Login)
 			auth.POST("/refresh", loginHandler.RefreshToken)
+			auth.POST("/logout", loginHandler.Logout)
+			auth.POST("/reactivate", loginHandler.Reactivate)
 		} else {
 			// 备用处理器
 			auth.POST("/login", func(c *gin.Context) {
@@ -157,6 +432,56 @@ func setupUserRoutes(rg *gin.RouterGroup) {
 		return
 	}
 
+	// 登录会话管理路由（需要认证，模拟登录时禁止）
+	sessions := auth.Group("/sessions", authMiddleware.RequireAuth(), authMiddleware.BlockImpersonation())
+	{
+		sessions.GET("", loginHandler.ListSessions)
+		sessions.DELETE("/:id", loginHandler.RevokeSession)
+	}
+
+	// 短信验证码登录路由，启动时未启用短信服务则不注册，两步验证也不提供短信方式
+	smsEnabled := verification.DefaultPhoneOptions().Sender != nil
+	if smsEnabled {
+		loginHandler.SetVerificationService(newVerificationService())
+		auth.POST("/sms/send", loginHandler.SendLoginSMS)
+		auth.POST("/sms/login", loginHandler.LoginWithSMS)
+	}
+
+	// 两步验证路由，启动时未创建两步验证服务则不注册，登录也不检查两步验证
+	if twoFactorService := user.DefaultTwoFactorService(); twoFactorService != nil {
+		loginHandler.SetTwoFactorService(twoFactorService)
+		auth.POST("/2fa/verify", loginHandler.VerifyTwoFactor)
+		if smsEnabled {
+			auth.POST("/2fa/sms", loginHandler.SendTwoFactorSMS)
+		}
+
+		twoFactorHandler := handlers.NewTwoFactorHandler(twoFactorService, getLogger())
+		twoFactor := auth.Group("/2fa", authMiddleware.RequireAuth(), authMiddleware.BlockImpersonation())
+		{
+			twoFactor.GET("", twoFactorHandler.GetStatus)
+			twoFactor.POST("/enroll", twoFactorHandler.Enroll)
+			twoFactor.POST("/enable", twoFactorHandler.Enable)
+			twoFactor.POST("/disable", twoFactorHandler.Disable)
+			twoFactor.POST("/backup-codes", twoFactorHandler.RegenerateBackupCodes)
+		}
+	}
+
+	// 企业目录认证，启动时未启用LDAP则密码登录只使用本地账户
+	if authenticator := sso.DefaultLDAP(); authenticator != nil {
+		loginHandler.SetLDAPAuthenticator(authenticator)
+	}
+
+	// 企业单点登录路由，启动时未启用单点登录则不注册，密码登录也不检查强制SSO
+	if ssoService := sso.Default(); ssoService != nil {
+		loginHandler.SetSSOService(ssoService)
+		ssoGroup := auth.Group("/sso")
+		{
+			ssoGroup.GET("/discover", loginHandler.DiscoverSSO)
+			ssoGroup.POST("/authorize", loginHandler.AuthorizeSSO)
+			ssoGroup.POST("/callback", loginHandler.SSOCallback)
+		}
+	}
+
 	// 用户管理路由（需要认证）
 	users := rg.Group("/users")
 	users.Use(authMiddleware.RequireAuth()) // 使用JWT认证中间件
@@ -168,12 +493,37 @@ func setupUserRoutes(rg *gin.RouterGroup) {
 		users.GET("/profile", func(c *gin.Context) {
 			c.JSON(200, gin.H{"message": "获取用户信息接口 - 待实现"})
 		})
-		users.PUT("/profile", func(c *gin.Context) {
+		// 修改资料(含邮箱)和密码为敏感操作，模拟登录时禁止
+		users.PUT("/profile", authMiddleware.BlockImpersonation(), func(c *gin.Context) {
 			c.JSON(200, gin.H{"message": "更新用户信息接口 - 待实现"})
 		})
-		users.POST("/change-password", func(c *gin.Context) {
+		users.POST("/change-password", authMiddleware.BlockImpersonation(), func(c *gin.Context) {
 			c.JSON(200, gin.H{"message": "修改密码接口 - 待实现"})
 		})
+		quotaHandler := handlers.NewStorageQuotaHandler(storageQuotaService(), getLogger())
+		quotaHandler.SetTrashUsage(filerepo.NewTrashRepository(database.GetDB()))
+		users.GET("/me/quota", quotaHandler.GetMyQuota)
+		if usageService := usagesvc.Default(); usageService != nil {
+			usageHandler := handlers.NewAPIUsageHandler(usageService, getLogger())
+			users.GET("/me/usage", usageHandler.GetMyUsage)
+			users.GET("/me/usage/export", usageHandler.ExportMyUsage)
+		}
+		// 管理API密钥需要登录会话，API密钥和模拟登录不能访问
+		if apiKeyService := newAPIKeyService(); apiKeyService != nil {
+			apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService, getLogger())
+			apiKeyHandler.SetSecurityAuditService(auditsvc.DefaultSecurity())
+			apiKeys := users.Group("/me/api-keys", authMiddleware.BlockImpersonation())
+			apiKeys.GET("", apiKeyHandler.ListAPIKeys)
+			apiKeys.POST("", apiKeyHandler.CreateAPIKey)
+			apiKeys.DELETE("/:id", apiKeyHandler.RevokeAPIKey)
+		}
+		// 合并账户需要分别登录两个账户，模拟登录时禁止
+		if mergeService := newAccountMergeService(); mergeService != nil {
+			mergeHandler := handlers.NewAccountMergeHandler(mergeService, getLogger())
+			mergeHandler.SetSecurityAuditService(auditsvc.DefaultSecurity())
+			users.POST("/me/merge-token", authMiddleware.BlockImpersonation(), mergeHandler.CreateMergeToken)
+			users.POST("/me/merge", authMiddleware.BlockImpersonation(), mergeHandler.MergeAccount)
+		}
 		users.GET("/:id", authMiddleware.RequireRole("admin"), func(c *gin.Context) {
 			c.JSON(200, gin.H{"message": "获取用户详情接口 - 待实现"})
 		})
@@ -188,38 +538,1523 @@ func setupUserRoutes(rg *gin.RouterGroup) {
 
 // setupFileRoutes 设置文件相关路由
 func setupFileRoutes(rg *gin.RouterGroup) {
+	fileService := filesvc.NewFileService(filerepo.NewFileRepository(database.GetDB()), getLogger())
+	checksumHandler := handlers.NewFileChecksumHandler(fileService, getLogger())
+	progressHub := filesvc.NewProgressHub()
+	progressHandler := handlers.NewUploadProgressHandler(progressHub, getLogger())
+	directUploadHandler := newDirectUploadHandler()
+	chunkedUploadService := newChunkedUploadService(progressHub)
+	var chunkedUploadHandler *handlers.ChunkedUploadHandler
+	if chunkedUploadService != nil {
+		chunkedUploadHandler = handlers.NewChunkedUploadHandler(chunkedUploadService, getLogger())
+	}
+	archiveHandler := newFileArchiveHandler()
+	downloadHandler := newFileDownloadHandler()
+	sandbox := newSandboxMiddleware()
+	trashService := newFileTrashService()
+	trashHandler := newFileTrashHandler(trashService)
+	treeHandler := newFileTreeHandler()
+	tagHandler := newFileTagHandler()
+	batchHandler := newFileBatchHandler(trashService)
+	metadataHandler := newFileMetadataHandler()
+	versionHandler := newFileVersionHandler()
+
+	// 获得授权的用户可以访问他人的文件和文件夹；上传始终只能上传到自己的空间
+	permissions := newPermissionService()
+	checksumHandler.SetFileAuthorizer(permissions)
+	exportHandler := newFileExportHandler(permissions)
+	if archiveHandler != nil {
+		archiveHandler.SetFileAuthorizer(permissions)
+	}
+	if downloadHandler != nil {
+		downloadHandler.SetFileAuthorizer(permissions)
+	}
+	if trashHandler != nil {
+		trashHandler.SetFileAuthorizer(permissions)
+	}
+	tagHandler.SetFileAuthorizer(permissions)
+	batchHandler.SetFileAuthorizer(permissions)
+	metadataHandler.SetFileAuthorizer(permissions)
+	if versionHandler != nil {
+		versionHandler.SetFileAuthorizer(permissions)
+	}
+	if treeHandler != nil {
+		treeHandler.SetFileAuthorizer(permissions)
+		if chunkedUploadService != nil {
+			// 文件夹列表中的上传占位文件带上传进度
+			treeHandler.SetUploadProgress(chunkedUploadService)
+		}
+	}
+
+	if scanService := newFileScanService(); scanService != nil {
+		if chunkedUploadHandler != nil {
+			chunkedUploadHandler.SetScanScheduler(scanService)
+		}
+		if directUploadHandler != nil {
+			directUploadHandler.SetScanScheduler(scanService)
+		}
+		if versionHandler != nil {
+			versionHandler.SetScanScheduler(scanService)
+		}
+	}
+
+	var previewHandler *handlers.FilePreviewHandler
+	if previewService := newFilePreviewService(); previewService != nil {
+		previewHandler = handlers.NewFilePreviewHandler(previewService, getLogger())
+		previewHandler.SetFileAuthorizer(permissions)
+		if chunkedUploadHandler != nil {
+			chunkedUploadHandler.SetPreviewScheduler(previewService)
+		}
+		if directUploadHandler != nil {
+			directUploadHandler.SetPreviewScheduler(previewService)
+		}
+		if versionHandler != nil {
+			versionHandler.SetPreviewScheduler(previewService)
+		}
+	}
+	if engine := newAutomationEngine(); engine != nil {
+		if chunkedUploadHandler != nil {
+			chunkedUploadHandler.SetUploadDispatcher(engine)
+		}
+		if directUploadHandler != nil {
+			directUploadHandler.SetUploadDispatcher(engine)
+		}
+	}
+
 	files := rg.Group("/files")
 	{
 		// 预留文件路由
-		files.GET("", func(c *gin.Context) {
-			c.JSON(200, gin.H{"message": "文件列表接口 - 待实现"})
-		})
 		files.POST("/upload", func(c *gin.Context) {
 			c.JSON(200, gin.H{"message": "文件上传接口 - 待实现"})
 		})
-		files.GET("/:id/download", func(c *gin.Context) {
-			c.JSON(200, gin.H{"message": "文件下载接口 - 待实现"})
-		})
-		files.DELETE("/:id", func(c *gin.Context) {
-			c.JSON(200, gin.H{"message": "删除文件接口 - 待实现"})
-		})
+	}
+
+	// 初始化认证中间件
+	authMiddleware, err := middleware.NewAuthMiddleware(config.AppConfig.JWT.Secret, getLogger())
+	if err != nil {
+		getLogger().Error("Failed to create auth middleware", zap.Error(err))
+		return
+	}
+
+	// 需要认证的文件路由，申请上传前先检查剩余存储空间
+	authed := files.Group("")
+	authed.Use(authMiddleware.RequireAuth())
+	quotaCheck := middleware.StorageQuota(storageQuotaService())
+	{
+		authed.GET("/:id/checksum", checksumHandler.GetFolderChecksum)
+		authed.GET("/uploads/:upload_id/progress", progressHandler.StreamUploadProgress)
+		if chunkedUploadHandler != nil {
+			authed.POST("/uploads", quotaCheck, chunkedUploadHandler.InitiateUpload)
+			authed.GET("/uploads/:upload_id", chunkedUploadHandler.GetUpload)
+			authed.DELETE("/uploads/:upload_id", chunkedUploadHandler.AbortUpload)
+			authed.PUT("/uploads/:upload_id/chunks/:index", chunkedUploadHandler.UploadChunk)
+			authed.POST("/uploads/:upload_id/merge", chunkedUploadHandler.MergeUpload)
+		}
+		if downloadHandler != nil {
+			authed.GET("/:id/download", sandbox, downloadHandler.Download)
+		}
+		if previewHandler != nil {
+			authed.GET("/:id/preview", sandbox, previewHandler.GetPreview)
+		}
+		if exportHandler != nil {
+			authed.GET("/:id/export", exportHandler.ExportFolder)
+		}
+		if directUploadHandler != nil {
+			authed.POST("/direct-uploads", quotaCheck, directUploadHandler.InitiateDirectUpload)
+			authed.POST("/direct-uploads/:upload_id/complete", directUploadHandler.CompleteDirectUpload)
+		}
+		if archiveHandler != nil {
+			authed.GET("/:id/archive", archiveHandler.GetArchiveStatus)
+			authed.POST("/:id/restore", archiveHandler.RequestRestore)
+		}
+		if trashHandler != nil {
+			authed.DELETE("/:id", trashHandler.MoveToTrash)
+		}
+		searchHandler := newFileSearchHandler()
+		authed.GET("/search", searchHandler.SearchFiles)
+		authed.GET("/search/history", searchHandler.GetSearchHistory)
+		authed.DELETE("/search/history", searchHandler.ClearSearchHistory)
+		if treeHandler != nil {
+			authed.GET("", treeHandler.ListFiles)
+			authed.POST("/folders", treeHandler.CreateFolder)
+			authed.PATCH("/:id", treeHandler.RenameFile)
+			authed.POST("/:id/move", treeHandler.MoveFile)
+			authed.POST("/:id/copy", treeHandler.CopyFile)
+		}
+		authed.GET("/tags", tagHandler.ListUserTags)
+		authed.GET("/:id/tags", tagHandler.ListFileTags)
+		authed.POST("/:id/tags", tagHandler.AddFileTag)
+		authed.DELETE("/:id/tags/*tag", tagHandler.RemoveFileTag)
+		authed.POST("/batch", batchHandler.BatchFiles)
+		authed.POST("/metadata:bulk", metadataHandler.BulkUpdateMetadata)
+		if versionHandler != nil {
+			authed.PUT("/:id/content", versionHandler.OverwriteContent)
+			authed.GET("/:id/versions", versionHandler.ListVersions)
+			authed.GET("/:id/versions/:number/download", sandbox, versionHandler.DownloadVersion)
+			authed.POST("/:id/versions/:number/restore", versionHandler.RestoreVersion)
+		}
+		if clipboardHandler := newFileClipboardHandler(); clipboardHandler != nil {
+			authed.GET("/clipboard", clipboardHandler.GetClipboard)
+			authed.PUT("/clipboard", clipboardHandler.SetClipboard)
+			authed.DELETE("/clipboard", clipboardHandler.ClearClipboard)
+			authed.POST("/clipboard/paste", clipboardHandler.PasteClipboard)
+		}
+	}
+
+	// 文件夹打包下载，逐个条目检查权限
+	if exportHandler != nil {
+		folders := rg.Group("/folders")
+		folders.Use(authMiddleware.RequireAuth())
+		folders.GET("/:id/download", sandbox, exportHandler.DownloadFolder)
+	}
+
+	// 回收站路由
+	if trashHandler != nil {
+		trash := rg.Group("/trash")
+		trash.Use(authMiddleware.RequireAuth())
+		{
+			trash.GET("", trashHandler.ListTrash)
+			trash.GET("/settings", trashHandler.GetTrashSettings)
+			trash.PUT("/settings", trashHandler.UpdateTrashSettings)
+			// 清空回收站需先获取确认令牌，项目较多时在后台执行
+			trash.POST("/empty/confirmation", trashHandler.ConfirmEmptyTrash)
+			trash.POST("/empty", trashHandler.EmptyTrash)
+			trash.GET("/empty/:job_id", trashHandler.GetEmptyTrashJob)
+			trash.POST("/:id/restore", trashHandler.RestoreTrashItem)
+			trash.DELETE("/:id", trashHandler.DeleteTrashItem)
+		}
 	}
 }
 
-// setupTeamRoutes 设置团队相关路由
-func setupTeamRoutes(rg *gin.RouterGroup) {
-	teams := rg.Group("/teams")
+// newPermissionService 创建文件访问权限服务
+func newPermissionService() acl.PermissionService {
+	db := database.GetDB()
+	return acl.NewPermissionService(
+		filerepo.NewGrantRepository(db),
+		filerepo.NewFileRepository(db),
+		userrepo.NewUserRepository(db),
+		teamrepo.NewTeamRepository(db),
+		getLogger(),
+	)
+}
+
+// newVerificationService 创建验证码服务，Redis未初始化时每次验证都查询数据库，短信发送次数也按数据库记录统计
+func newVerificationService() verification.VerificationService {
+	var codeCache verification.CodeCache
+	if cache.RedisClient != nil {
+		codeCache = cache.NewCacheManager()
+	}
+	return verification.NewVerificationService(database.GetDB(), nil, codeCache, nil, getLogger())
+}
+
+// newFileSearchHandler 创建文件搜索处理器
+//
+// Redis未初始化时不缓存结果页；未设置全局搜索历史存储时不记录搜索历史
+func newFileSearchHandler() *handlers.FileSearchHandler {
+	var resultCache filesvc.SearchResultCache
+	if cache.RedisClient != nil {
+		resultCache = cache.NewCacheManager()
+	}
+
+	service := filesvc.NewSearchService(
+		filerepo.NewSearchRepository(database.GetDB()),
+		resultCache,
+		cache.DefaultSearchHistoryStore(),
+		getLogger(),
+	)
+	return handlers.NewFileSearchHandler(service, getLogger())
+}
+
+// newFileTreeHandler 创建文件重命名、移动和复制处理器，存储不可用时返回nil
+func newFileTreeHandler() *handlers.FileTreeHandler {
+	service := newFileTreeService()
+	if service == nil {
+		return nil
+	}
+	return handlers.NewFileTreeHandler(service, getLogger())
+}
+
+// newFileTagHandler 创建文件标签处理器
+func newFileTagHandler() *handlers.FileTagHandler {
+	return handlers.NewFileTagHandler(newFileTagService(), getLogger())
+}
+
+// newFileTagService 创建文件标签服务
+func newFileTagService() filesvc.TagService {
+	db := database.GetDB()
+	return filesvc.NewTagService(filerepo.NewTagRepository(db), filerepo.NewFileRepository(db), getLogger())
+}
+
+// newFileBatchHandler 创建文件批量操作处理器，trash为nil时不支持删除和恢复
+//
+// 存储不可用时不支持移动和复制
+func newFileBatchHandler(trash filesvc.TrashService) *handlers.FileBatchHandler {
+	handler := handlers.NewFileBatchHandler(newFileTreeService(), trash, newFileTagService(), getLogger())
+	handler.SetSecurityAuditService(auditsvc.DefaultSecurity())
+	return handler
+}
+
+// newFileMetadataHandler 创建批量编辑文件标签和属性处理器，每批文件在一个数据库事务中修改
+func newFileMetadataHandler() *handlers.FileMetadataHandler {
+	db := database.GetDB()
+	service := filesvc.NewMetadataService(filerepo.NewTagRepository(db), filerepo.NewFileRepository(db), database.NewTxManager(db), getLogger())
+	handler := handlers.NewFileMetadataHandler(service, getLogger())
+	handler.SetSecurityAuditService(auditsvc.DefaultSecurity())
+	return handler
+}
+
+// newFileVersionHandler 创建文件历史版本处理器，存储不可用时返回nil
+func newFileVersionHandler() *handlers.FileVersionHandler {
+	store, err := storage.NewFromConfig(config.AppConfig.Storage)
+	if err != nil {
+		getLogger().Warn("File versions disabled: storage unavailable", zap.Error(err))
+		return nil
+	}
+	return handlers.NewFileVersionHandler(newFileVersionService(store), getLogger())
+}
+
+// newFileVersionService 创建文件历史版本服务
+func newFileVersionService(store storage.Storage) filesvc.VersionService {
+	// Redis未初始化时不清除缓存，避免延迟初始化时直接退出
+	var cacheDeleter filesvc.CacheDeleter
+	if cache.RedisClient != nil {
+		cacheDeleter = cache.NewCacheManager()
+	}
+
+	db := database.GetDB()
+	fileRepo := filerepo.NewFileRepository(db)
+	return filesvc.NewVersionService(
+		fileRepo,
+		filerepo.NewVersionRepository(db),
+		userrepo.NewUserRepository(db),
+		store,
+		filesvc.NewFileService(fileRepo, getLogger()),
+		cacheDeleter,
+		filesvc.VersionOptionsFromConfig(config.AppConfig.Storage.Versions),
+		getLogger(),
+	)
+}
+
+// newFileClipboardHandler 创建服务端剪贴板处理器，未设置全局剪贴板存储或存储不可用时返回nil
+func newFileClipboardHandler() *handlers.FileClipboardHandler {
+	store := cache.DefaultClipboardStore()
+	if store == nil {
+		return nil
+	}
+	tree := newFileTreeService()
+	if tree == nil {
+		return nil
+	}
+	db := database.GetDB()
+	service := filesvc.NewClipboardService(
+		filerepo.NewFileRepository(db),
+		filerepo.NewFolderRepository(db),
+		tree,
+		store,
+		getLogger(),
+	)
+	return handlers.NewFileClipboardHandler(service, getLogger())
+}
+
+// newFileTreeService 创建文件树操作服务，存储不可用时返回nil
+func newFileTreeService() filesvc.TreeService {
+	store, err := storage.NewFromConfig(config.AppConfig.Storage)
+	if err != nil {
+		getLogger().Warn("File move and copy disabled: storage unavailable", zap.Error(err))
+		return nil
+	}
+
+	// Redis未初始化时不清除缓存，避免延迟初始化时直接退出
+	var cacheDeleter filesvc.CacheDeleter
+	if cache.RedisClient != nil {
+		cacheDeleter = cache.NewCacheManager()
+	}
+
+	db := database.GetDB()
+	fileRepo := filerepo.NewFileRepository(db)
+	return filesvc.NewTreeService(
+		fileRepo,
+		filerepo.NewFolderRepository(db),
+		userrepo.NewUserRepository(db),
+		store,
+		filesvc.NewFileService(fileRepo, getLogger()),
+		cacheDeleter,
+		folderLimiter(),
+		filesvc.TreeOptions{},
+		getLogger(),
+	)
+}
+
+// newAutomationEngine 创建文件夹规则执行引擎，未启用文件夹规则时返回nil
+//
+// 存储不可用时移动动作执行失败；任务队列未启动时上传完成不触发规则
+func newAutomationEngine() *automation.Engine {
+	automationConfig := config.AppConfig.Automation
+	if !automationConfig.Enabled {
+		return nil
+	}
+
+	var mover automation.FileMover
+	if tree := newFileTreeService(); tree != nil {
+		mover = tree
+	}
+	var queue automation.JobEnqueuer
+	if q := jobs.Default(); q != nil {
+		queue = q
+	}
+	db := database.GetDB()
+	return automation.NewEngine(
+		filerepo.NewFolderRuleRepository(db),
+		filerepo.NewFileRepository(db),
+		mover,
+		queue,
+		automation.EngineOptionsFromConfig(automationConfig),
+		getLogger(),
+	)
+}
+
+// setupFolderRuleRoutes 设置文件夹自动化规则路由，未启用文件夹规则时不注册
+//
+// 维护任务调度器已启用时同时注册执行日志清理任务
+func setupFolderRuleRoutes(rg *gin.RouterGroup) {
+	automationConfig := config.AppConfig.Automation
+	if !automationConfig.Enabled {
+		return
+	}
+
+	db := database.GetDB()
+	service := automation.NewRuleService(
+		filerepo.NewFolderRuleRepository(db),
+		filerepo.NewFileRepository(db),
+		automation.RuleOptionsFromConfig(automationConfig),
+		getLogger(),
+	)
+	if scheduler := maintenance.Default(); scheduler != nil {
+		task := maintenance.Task{Name: maintenance.TaskFolderRuleLogs, Run: func(ctx context.Context) (int64, error) {
+			return service.PurgeLogs(ctx, scheduler.BatchSize())
+		}}
+		if err := scheduler.Register(task); err != nil {
+			getLogger().Warn("Failed to register folder rule log cleanup", zap.Error(err))
+		}
+	}
+	handler := handlers.NewFolderRuleHandler(service, getLogger())
+
+	authMiddleware, err := middleware.NewAuthMiddleware(config.AppConfig.JWT.Secret, getLogger())
+	if err != nil {
+		getLogger().Error("Failed to create auth middleware", zap.Error(err))
+		return
+	}
+
+	rules := rg.Group("/folder-rules")
+	rules.Use(authMiddleware.RequireAuth())
 	{
-		// 预留团队路由
-		teams.GET("", func(c *gin.Context) {
-			c.JSON(200, gin.H{"message": "团队列表接口 - 待实现"})
-		})
-		teams.POST("", func(c *gin.Context) {
-			c.JSON(200, gin.H{"message": "创建团队接口 - 待实现"})
-		})
-		teams.GET("/:id", func(c *gin.Context) {
-			c.JSON(200, gin.H{"message": "获取团队详情接口 - 待实现"})
-		})
+		rules.GET("", handler.ListRules)
+		rules.POST("", handler.CreateRule)
+		rules.PATCH("/:id", handler.UpdateRule)
+		rules.DELETE("/:id", handler.DeleteRule)
+		rules.GET("/:id/logs", handler.ListRuleLogs)
+	}
+}
+
+// setupFileTransferRoutes 设置文件所有权转移路由
+//
+// 维护任务调度器已启用时同时注册过期转移清理任务
+func setupFileTransferRoutes(rg *gin.RouterGroup) {
+	service := newFileTransferService()
+	if scheduler := maintenance.Default(); scheduler != nil {
+		task := maintenance.Task{Name: maintenance.TaskFileTransfers, Run: func(ctx context.Context) (int64, error) {
+			return service.ExpirePending(ctx, scheduler.BatchSize())
+		}}
+		if err := scheduler.Register(task); err != nil {
+			getLogger().Warn("Failed to register file transfer expiry", zap.Error(err))
+		}
+	}
+	handler := handlers.NewFileTransferHandler(service, getLogger())
+	handler.SetSecurityAuditService(auditsvc.DefaultSecurity())
+
+	authMiddleware, err := middleware.NewAuthMiddleware(config.AppConfig.JWT.Secret, getLogger())
+	if err != nil {
+		getLogger().Error("Failed to create auth middleware", zap.Error(err))
+		return
+	}
+
+	transfers := rg.Group("/transfers")
+	transfers.Use(authMiddleware.RequireAuth())
+	{
+		transfers.POST("", handler.InitiateTransfer)
+		transfers.GET("", handler.ListTransfers)
+		transfers.GET("/:id", handler.GetTransfer)
+		transfers.POST("/:id/accept", handler.AcceptTransfer)
+		transfers.POST("/:id/decline", handler.DeclineTransfer)
+		transfers.POST("/:id/cancel", handler.CancelTransfer)
+		// 完成失败的已接受转移由发起方或接收方重试
+		transfers.POST("/:id/complete", handler.CompleteTransfer)
+	}
+}
+
+// newFileTransferService 创建文件所有权转移服务
+func newFileTransferService() filesvc.TransferService {
+	// Redis未初始化时不清除缓存，避免延迟初始化时直接退出
+	var cacheDeleter filesvc.CacheDeleter
+	if cache.RedisClient != nil {
+		cacheDeleter = cache.NewCacheManager()
+	}
+
+	db := database.GetDB()
+	fileRepo := filerepo.NewFileRepository(db)
+	return filesvc.NewTransferService(
+		fileRepo,
+		filerepo.NewFolderRepository(db),
+		filerepo.NewTransferRepository(db),
+		userrepo.NewUserRepository(db),
+		filerepo.NewVersionRepository(db),
+		filesvc.NewFileService(fileRepo, getLogger()),
+		cacheDeleter,
+		folderLimiter(),
+		filesvc.TransferOptionsFromConfig(config.AppConfig.Storage.Transfer),
+		getLogger(),
+	)
+}
+
+// newAccountMergeService 创建账户合并服务，合并令牌存储未初始化时返回nil
+func newAccountMergeService() user.AccountMergeService {
+	tokens := cache.DefaultMergeTokenStore()
+	if tokens == nil {
+		return nil
+	}
+	db := database.GetDB()
+	return user.NewAccountMergeService(
+		userrepo.NewUserRepository(db),
+		userrepo.NewAccountMergeRepository(db),
+		newFileTransferService(),
+		storageQuotaService(),
+		tokens,
+		cache.DefaultTokenStore(),
+		getLogger(),
+	)
+}
+
+// newFileTrashHandler 创建回收站处理器，回收站服务不可用时返回nil
+func newFileTrashHandler(service filesvc.TrashService) *handlers.FileTrashHandler {
+	if service == nil {
+		return nil
+	}
+	trashHandler := handlers.NewFileTrashHandler(service, getLogger())
+	trashHandler.SetSecurityAuditService(auditsvc.DefaultSecurity())
+	return trashHandler
+}
+
+// newFileTrashService 创建回收站服务，存储不可用时返回nil
+//
+// 维护任务调度器已启用时同时注册回收站自动清理任务
+func newFileTrashService() filesvc.TrashService {
+	store, err := storage.NewFromConfig(config.AppConfig.Storage)
+	if err != nil {
+		getLogger().Warn("File trash disabled: storage unavailable", zap.Error(err))
+		return nil
+	}
+
+	db := database.GetDB()
+	fileRepo := filerepo.NewFileRepository(db)
+	userRepo := userrepo.NewUserRepository(db)
+	retention := newContentRetentionService(store)
+	service := filesvc.NewTrashService(
+		fileRepo,
+		filerepo.NewTrashRepository(db),
+		userRepo,
+		store,
+		filesvc.NewFileService(fileRepo, getLogger()),
+		retention,
+		newFileVersionService(store),
+		userRepo,
+		filesvc.TrashOptionsFromConfig(config.AppConfig.Storage.Trash),
+		getLogger(),
+	)
+	if scheduler := maintenance.Default(); scheduler != nil {
+		tasks := []maintenance.Task{
+			{Name: maintenance.TaskTrash, Run: func(ctx context.Context) (int64, error) {
+				return service.PurgeExpired(ctx, scheduler.BatchSize())
+			}},
+			{Name: maintenance.TaskRetainedContent, Run: func(ctx context.Context) (int64, error) {
+				return retention.PurgeExpired(ctx, scheduler.BatchSize())
+			}},
+		}
+		for _, task := range tasks {
+			if err := scheduler.Register(task); err != nil {
+				getLogger().Warn("Failed to register trash cleanup", zap.String("task", task.Name), zap.Error(err))
+			}
+		}
+	}
+	return service
+}
+
+// newContentRetentionService 创建删除后存储对象保留服务
+func newContentRetentionService(store storage.Storage) filesvc.ContentRetentionService {
+	db := database.GetDB()
+	return filesvc.NewContentRetentionService(
+		filerepo.NewRetainedObjectRepository(db),
+		userrepo.NewUserRepository(db),
+		filerepo.NewTrashRepository(db),
+		store,
+		filesvc.ContentRetentionOptionsFromConfig(config.AppConfig.Storage.Trash),
+		getLogger(),
+	)
+}
+
+// newFileDownloadHandler 创建文件下载处理器，存储不可用时返回nil
+func newFileDownloadHandler() *handlers.FileDownloadHandler {
+	store, err := storage.NewFromConfig(config.AppConfig.Storage)
+	if err != nil {
+		getLogger().Warn("File download disabled: storage unavailable", zap.Error(err))
+		return nil
+	}
+
+	service := filesvc.NewDownloadService(filerepo.NewFileRepository(database.GetDB()), store, getLogger())
+	return handlers.NewFileDownloadHandler(service, getLogger())
+}
+
+// newFileScanService 创建病毒扫描服务，存储不可用时返回nil
+//
+// 未启用病毒扫描或任务队列未启动时上传的文件标记为未扫描(skipped)
+func newFileScanService() filesvc.ScanService {
+	store, err := storage.NewFromConfig(config.AppConfig.Storage)
+	if err != nil {
+		getLogger().Warn("Virus scan disabled: storage unavailable", zap.Error(err))
+		return nil
+	}
+
+	antivirusConfig := config.AppConfig.Security.Antivirus
+	var scanner antivirus.Scanner
+	if antivirusConfig.Enabled {
+		scanner = &antivirus.ClamAV{Address: antivirusConfig.ClamAVSocket, Timeout: antivirusConfig.ScanTimeout}
+	}
+	var queue filesvc.JobEnqueuer
+	if q := jobs.Default(); q != nil {
+		queue = q
+	}
+	return filesvc.NewScanService(
+		filerepo.NewFileRepository(database.GetDB()),
+		store,
+		scanner,
+		queue,
+		filesvc.ScanOptionsFromConfig(antivirusConfig),
+		getLogger(),
+	)
+}
+
+// newSandboxMiddleware 创建用户内容沙箱中间件，用于预览、缩略图和下载接口
+func newSandboxMiddleware() gin.HandlerFunc {
+	sandboxConfig := config.AppConfig.Storage.Preview.Sandbox
+	return middleware.Sandbox(middleware.SandboxOptions{
+		Host:          sandboxConfig.Host,
+		ScriptSources: sandboxConfig.ScriptSources,
+		DisableHTML:   sandboxConfig.DisableHTML,
+	})
+}
+
+// newFilePreviewService 创建文件预览服务，未启用预览或存储不可用时返回nil
+//
+// 任务队列未启动时仍可读取已生成的预览，但不再生成新的预览
+func newFilePreviewService() filesvc.PreviewService {
+	previewConfig := config.AppConfig.Storage.Preview
+	if !previewConfig.Enabled {
+		return nil
+	}
+	store, err := storage.NewFromConfig(config.AppConfig.Storage)
+	if err != nil {
+		getLogger().Warn("File preview disabled: storage unavailable", zap.Error(err))
+		return nil
+	}
+
+	var queue filesvc.JobEnqueuer
+	if q := jobs.Default(); q != nil {
+		queue = q
+	}
+	return filesvc.NewPreviewService(
+		filerepo.NewFileRepository(database.GetDB()),
+		store,
+		queue,
+		filesvc.PreviewOptionsFromConfig(previewConfig),
+		getLogger(),
+	)
+}
+
+// newFileExportHandler 创建文件夹导出处理器，存储不可用时返回nil
+func newFileExportHandler(authorizer filesvc.ExportAuthorizer) *handlers.FileExportHandler {
+	store, err := storage.NewFromConfig(config.AppConfig.Storage)
+	if err != nil {
+		getLogger().Warn("Folder export disabled: storage unavailable", zap.Error(err))
+		return nil
+	}
+
+	exportConfig := config.AppConfig.Storage.Export
+	exporter := filesvc.NewFolderExporter(
+		filerepo.NewFileRepository(database.GetDB()),
+		store,
+		filesvc.NewExportLimiter(exportConfig.MaxConcurrentPerUser, exportConfig.MaxConcurrent),
+		filesvc.ExportOptions{MaxFiles: exportConfig.MaxFiles, MaxTotalSize: exportConfig.MaxTotalSize},
+		getLogger(),
+	)
+	exporter.SetAuthorizer(authorizer)
+	return handlers.NewFileExportHandler(exporter, getLogger())
+}
+
+// storageQuotaService 返回全局存储配额记账服务，启动时未创建则按数据库创建
+func storageQuotaService() user.StorageQuotaService {
+	if service := user.DefaultStorageQuotaService(); service != nil {
+		return service
+	}
+	db := database.GetDB()
+	return user.NewStorageQuotaService(userrepo.NewStorageReservationRepository(db), userrepo.NewUserRepository(db), getLogger())
+}
+
+// newInactivityService 按配置创建长期未登录账户处置服务
+func newInactivityService() user.InactivityService {
+	return user.NewInactivityService(
+		userrepo.NewInactivityRepository(database.GetDB()),
+		email.GlobalQueue{},
+		cache.DefaultTokenStore(),
+		auditsvc.DefaultSecurity(),
+		user.InactivityOptionsFromConfig(config.AppConfig.App.Name, config.AppConfig.User.Inactivity),
+		getLogger(),
+	)
+}
+
+// folderLimiter 按配置创建文件夹层级和子项数限制
+func folderLimiter() filesvc.FolderLimiter {
+	db := database.GetDB()
+	return filesvc.NewFolderLimiter(
+		filerepo.NewFolderRepository(db),
+		filerepo.NewFileRepository(db),
+		filesvc.FolderLimitOptionsFromConfig(config.AppConfig.Storage.Folders),
+		getLogger(),
+	)
+}
+
+// newChunkedUploadService 创建分片上传服务，存储不可用时返回nil
+func newChunkedUploadService(progress filesvc.ProgressPublisher) filesvc.ChunkedUploadService {
+	store, err := storage.NewFromConfig(config.AppConfig.Storage)
+	if err != nil {
+		getLogger().Warn("Chunked upload disabled: storage unavailable", zap.Error(err))
+		return nil
+	}
+
+	options := filesvc.ChunkedUploadOptionsFromConfig(config.AppConfig.Storage.Upload)
+	options.TTLTuner = filesvc.DefaultUploadTTLTuner()
+	options.Checksums = filesvc.NewFileService(filerepo.NewFileRepository(database.GetDB()), getLogger())
+	return filesvc.NewChunkedUploadService(
+		filerepo.NewFileRepository(database.GetDB()),
+		filerepo.NewUploadChunkRepository(database.GetDB()),
+		storageQuotaService(),
+		store,
+		progress,
+		folderLimiter(),
+		options,
+		clock.Real(),
+		idgen.Default(),
+		getLogger(),
+	)
+}
+
+// newDirectUploadHandler 创建浏览器直传处理器，未启用直传或OSS时返回nil
+func newDirectUploadHandler() *handlers.DirectUploadHandler {
+	directConfig := config.AppConfig.Storage.Direct
+	ossConfig := config.AppConfig.Storage.OSS
+	if !directConfig.Enabled {
+		return nil
+	}
+	if !ossConfig.Enabled {
+		getLogger().Warn("Direct upload disabled: OSS storage is not enabled")
+		return nil
+	}
+
+	presigner, err := storage.NewS3Presigner(storage.S3OptionsFromConfig(ossConfig), nil)
+	if err != nil {
+		getLogger().Warn("Direct upload disabled: invalid OSS configuration", zap.Error(err))
+		return nil
+	}
+
+	service := filesvc.NewDirectUploadService(
+		filerepo.NewFileRepository(database.GetDB()),
+		storageQuotaService(),
+		presigner,
+		folderLimiter(),
+		filesvc.DirectUploadOptions{
+			StorageType: storage.StorageTypeForProvider(ossConfig.Provider),
+			MinSize:     directConfig.MinSize,
+			MaxSize:     directConfig.MaxSize,
+			URLExpiry:   directConfig.URLExpiry,
+			KeyPrefix:   directConfig.KeyPrefix,
+			Checksums:   filesvc.NewFileService(filerepo.NewFileRepository(database.GetDB()), getLogger()),
+		},
+		getLogger(),
+	)
+	return handlers.NewDirectUploadHandler(service, directConfig.AllowedOrigins, getLogger())
+}
+
+// newFileArchiveHandler 创建归档文件恢复处理器，未启用归档或OSS时返回nil
+func newFileArchiveHandler() *handlers.FileArchiveHandler {
+	storageConfig := config.AppConfig.Storage
+	if !storageConfig.Archive.Enabled || !storageConfig.OSS.Enabled {
+		return nil
+	}
+
+	archiver, err := storage.NewS3Archiver(storage.S3OptionsFromConfig(storageConfig.OSS), nil)
+	if err != nil {
+		getLogger().Warn("File archive disabled: invalid OSS configuration", zap.Error(err))
+		return nil
+	}
+
+	service := filesvc.NewArchiveService(
+		filerepo.NewFileRepository(database.GetDB()),
+		archiver,
+		filesvc.ArchiveOptionsFromConfig(storageConfig.Archive),
+		getLogger(),
+	)
+	return handlers.NewFileArchiveHandler(service, getLogger())
+}
+
+// setupShareRoutes 设置文件分享路由
+func setupShareRoutes(rg *gin.RouterGroup) {
+	// 多实例部署时通过Redis共享密码尝试计数和访问/下载次数，Redis未初始化时退化为进程内计数
+	var limiter ratelimit.Limiter
+	var counter ratelimit.Counter
+	if cache.RedisClient != nil {
+		limiter = ratelimit.NewRedisLimiter(cache.RedisClient)
+		counter = ratelimit.NewRedisCounter(cache.RedisClient)
+	} else {
+		memoryLimiter, memoryCounter := ratelimit.NewMemoryLimiter(), ratelimit.NewMemoryCounter()
+		limiter, counter = memoryLimiter, memoryCounter
+		// 进程内计数由维护任务定期清理过期窗口
+		if scheduler := maintenance.Default(); scheduler != nil {
+			task := maintenance.PruneTask(maintenance.TaskRateLimits, memoryLimiter, memoryCounter)
+			if err := scheduler.Register(task); err != nil {
+				getLogger().Warn("Failed to register rate limit cleanup", zap.Error(err))
+			}
+		}
+	}
+
+	// 分享页元数据缓存在Redis中，Redis未初始化时直接查询数据库
+	var metadataCache sharesvc.MetadataCache
+	if cache.RedisClient != nil {
+		metadataCache = cache.NewCacheManager()
+	}
+	metadataService := sharesvc.NewMetadataService(
+		filerepo.NewShareRepository(database.GetDB()),
+		filerepo.NewFileRepository(database.GetDB()),
+		userrepo.NewUserRepository(database.GetDB()),
+		metadataCache,
+		clock.Real(),
+		getLogger(),
+	)
+
+	shareService := sharesvc.NewShareService(
+		filerepo.NewShareRepository(database.GetDB()),
+		systemrepo.NewSettingRepository(database.GetDB()),
+		userrepo.NewUserRepository(database.GetDB()),
+		notificationrepo.NewNotificationRepository(database.GetDB()),
+		metadataService,
+		limiter,
+		sharesvc.PolicyFromConfig(config.AppConfig.Share),
+		clock.Real(),
+		getLogger(),
+	)
+	accessService := sharesvc.NewAccessService(
+		shareService,
+		filerepo.NewShareRepository(database.GetDB()),
+		filerepo.NewFileRepository(database.GetDB()),
+		counter,
+		clock.Real(),
+		getLogger(),
+	)
+	shareHandler := handlers.NewShareHandler(shareService, getLogger())
+	creationHandler := handlers.NewShareCreationHandler(
+		sharesvc.NewCreationService(filerepo.NewShareRepository(database.GetDB()), filerepo.NewFileRepository(database.GetDB()), clock.Real(), idgen.Default(), getLogger()),
+		getLogger(),
+	)
+	creationHandler.SetFileAuthorizer(newPermissionService())
+	creationHandler.SetSecurityAuditService(auditsvc.DefaultSecurity())
+	templateService := sharesvc.NewTemplateService(userrepo.NewUserRepository(database.GetDB()), getLogger())
+	creationHandler.SetTemplateService(templateService)
+	moderationService := newShareModerationService(metadataService)
+	creationHandler.SetContentModerator(moderationService)
+	adminModerationHandler := handlers.NewAdminShareModerationHandler(moderationService, getLogger())
+	adminModerationHandler.SetAuditService(auditsvc.Default())
+	templateHandler := handlers.NewShareTemplateHandler(templateService, getLogger())
+	downloadHandler := newShareDownloadHandler(shareService, accessService)
+	accessHandler := handlers.NewShareAccessHandler(accessService, downloadHandler != nil, getLogger())
+	metadataHandler := handlers.NewShareMetadataHandler(metadataService, getLogger())
+	sandbox := newSandboxMiddleware()
+	if previewService := newFilePreviewService(); previewService != nil {
+		metadataHandler.SetPreviewService(previewService)
+	}
+	abuseService := sharesvc.NewAbuseService(
+		filerepo.NewAbuseReportRepository(database.GetDB()),
+		filerepo.NewShareRepository(database.GetDB()),
+		userrepo.NewUserRepository(database.GetDB()),
+		notificationrepo.NewNotificationRepository(database.GetDB()),
+		metadataService,
+		limiter,
+		newShareAbuseVerifier(),
+		sharesvc.AbusePolicyFromConfig(config.AppConfig.Share),
+		clock.Real(),
+		getLogger(),
+	)
+	abuseHandler := handlers.NewShareAbuseHandler(abuseService, getLogger())
+	adminAbuseHandler := handlers.NewAdminShareAbuseHandler(abuseService, getLogger())
+	adminAbuseHandler.SetAuditService(auditsvc.Default())
+
+	authMiddleware, err := middleware.NewAuthMiddleware(config.AppConfig.JWT.Secret, getLogger())
+	if err != nil {
+		getLogger().Error("Failed to create auth middleware", zap.Error(err))
+	}
+
+	// 访问者无需登录即可查看分享元数据、打开分享、验证分享密码、查询流量状态、下载和举报，已登录的访问者识别为当前用户
+	public := rg.Group("/public/shares")
+	if authMiddleware != nil {
+		public.Use(authMiddleware.OptionalAuth())
+	}
+	{
+		public.GET("/:code", accessHandler.Resolve)
+		public.GET("/:code/meta", metadataHandler.GetMetadata)
+		public.GET("/:code/thumbnail", sandbox, metadataHandler.GetThumbnail)
+		public.POST("/:code/verify", shareHandler.VerifyPassword)
+		public.GET("/:code/transfer", shareHandler.GetTransferStatus)
+		public.POST("/:code/report", abuseHandler.ReportShare)
+		if downloadHandler != nil {
+			public.GET("/:code/download", sandbox, downloadHandler.Download)
+		}
+	}
+
+	// 分享落地页使用的短路径，供前端渲染分享页和社交平台抓取预览
+	short := rg.Group("/s")
+	{
+		short.GET("/:code/meta", metadataHandler.GetMetadata)
+		short.GET("/:code/thumbnail", sandbox, metadataHandler.GetThumbnail)
+	}
+
+	if authMiddleware == nil {
+		return
+	}
+
+	shares := rg.Group("/shares", authMiddleware.RequireAuth())
+	{
+		shares.POST("", creationHandler.CreateShare)
+		shares.GET("/templates", templateHandler.ListTemplates)
+		shares.PUT("/templates/:name", templateHandler.SaveTemplate)
+		shares.DELETE("/templates/:name", templateHandler.DeleteTemplate)
+		shares.PUT("/templates/:name/default", templateHandler.SetDefaultTemplate)
+		shares.DELETE("/templates/:name/default", templateHandler.UnsetDefaultTemplate)
+		shares.PUT("/:id/password", shareHandler.SetPassword)
+		shares.DELETE("/:id", shareHandler.RevokeShare)
+		if downloadHandler != nil {
+			shares.GET("/privacy", downloadHandler.GetUserPrivacy)
+			shares.PUT("/privacy", downloadHandler.SetUserPrivacy)
+			shares.PUT("/:id/privacy", downloadHandler.SetSharePrivacy)
+		}
+	}
+
+	// 举报审核队列仅管理员可访问
+	adminReports := rg.Group("/admin/share-reports", authMiddleware.RequireAuth(), authMiddleware.RequireRole("admin"))
+	{
+		adminReports.GET("", adminAbuseHandler.ListReports)
+		adminReports.POST("/:id/resolve", adminAbuseHandler.ResolveReport)
+	}
+
+	// 内容审核复核队列仅管理员可访问
+	adminModeration := rg.Group("/admin/share-moderation", authMiddleware.RequireAuth(), authMiddleware.RequireRole("admin"))
+	{
+		adminModeration.GET("", adminModerationHandler.ListResults)
+		adminModeration.POST("/:id/override", adminModerationHandler.OverrideResult)
+	}
+}
+
+// newShareModerationService 创建分享图片内容审核服务
+//
+// 未启用内容审核、审核配置无效或存储不可用时分享不审核，仍可复核已有的审核结果
+func newShareModerationService(metadata sharesvc.MetadataInvalidator) sharesvc.ModerationService {
+	moderationConfig := config.AppConfig.Security.Moderation
+	scanner, err := moderation.FromConfig(moderationConfig)
+	if err != nil {
+		getLogger().Warn("Content moderation disabled: invalid moderation config", zap.Error(err))
+	}
+	store, err := storage.NewFromConfig(config.AppConfig.Storage)
+	if err != nil {
+		if scanner != nil {
+			getLogger().Warn("Content moderation disabled: storage unavailable", zap.Error(err))
+		}
+		scanner = nil
+	}
+
+	return sharesvc.NewModerationService(
+		filerepo.NewModerationRepository(database.GetDB()),
+		filerepo.NewFileRepository(database.GetDB()),
+		filerepo.NewShareRepository(database.GetDB()),
+		userrepo.NewUserRepository(database.GetDB()),
+		notificationrepo.NewNotificationRepository(database.GetDB()),
+		metadata,
+		store,
+		scanner,
+		sharesvc.ModerationPolicyFromConfig(moderationConfig),
+		clock.Real(),
+		getLogger(),
+	)
+}
+
+// newShareAbuseVerifier 创建分享举报的人机验证校验器，未要求或未启用人机验证时返回nil
+func newShareAbuseVerifier() captcha.Verifier {
+	if !config.AppConfig.Share.AbuseReportCaptcha {
+		return nil
+	}
+	verifier, err := captcha.FromConfig(config.AppConfig.Security.Captcha)
+	if err != nil {
+		getLogger().Warn("Share abuse captcha disabled: invalid captcha config", zap.Error(err))
+		return nil
+	}
+	if verifier == nil {
+		getLogger().Warn("Share abuse captcha required but security.captcha is not enabled")
+	}
+	return verifier
+}
+
+// newShareDownloadHandler 创建分享下载处理器，存储不可用时返回nil
+func newShareDownloadHandler(shareService sharesvc.ShareService, quota sharesvc.DownloadQuota) *handlers.ShareDownloadHandler {
+	store, err := storage.NewFromConfig(config.AppConfig.Storage)
+	if err != nil {
+		getLogger().Warn("Share download disabled: storage unavailable", zap.Error(err))
+		return nil
+	}
+
+	service := sharesvc.NewDownloadService(
+		shareService,
+		quota,
+		filerepo.NewShareRepository(database.GetDB()),
+		filerepo.NewFileRepository(database.GetDB()),
+		userrepo.NewUserRepository(database.GetDB()),
+		store,
+		sharesvc.PolicyFromConfig(config.AppConfig.Share),
+		getLogger(),
+	)
+	return handlers.NewShareDownloadHandler(service, getLogger())
+}
+
+// setupLimitsRoutes 设置有效限制查询路由
+func setupLimitsRoutes(rg *gin.RouterGroup) {
+	// Redis未初始化时不使用缓存，避免延迟初始化时直接退出
+	var cacheManager *cache.CacheManager
+	if cache.RedisClient != nil {
+		cacheManager = cache.NewCacheManager()
+	}
+
+	limitsService := limitssvc.NewLimitsService(
+		userrepo.NewUserRepository(database.GetDB()),
+		systemrepo.NewSettingRepository(database.GetDB()),
+		cacheManager,
+		getLogger(),
+	)
+	limitsHandler := handlers.NewLimitsHandler(limitsService, getLogger())
+
+	authMiddleware, err := middleware.NewAuthMiddleware(config.AppConfig.JWT.Secret, getLogger())
+	if err != nil {
+		getLogger().Error("Failed to create auth middleware", zap.Error(err))
+		return
+	}
+
+	rg.GET("/limits", authMiddleware.RequireAuth(), limitsHandler.GetLimits)
+}
+
+// setupFeatureRoutes 设置特性开关评估路由
+func setupFeatureRoutes(rg *gin.RouterGroup) {
+	featureFlagService := featureflagsvc.NewFeatureFlagService(
+		systemrepo.NewFeatureFlagRepository(database.GetDB()),
+		userrepo.NewUserRepository(database.GetDB()),
+		getLogger(),
+	)
+	featureFlagHandler := handlers.NewFeatureFlagHandler(featureFlagService, getLogger())
+	subscribeFeatureFlagInvalidation(featureFlagService)
+
+	authMiddleware, err := middleware.NewAuthMiddleware(config.AppConfig.JWT.Secret, getLogger())
+	if err != nil {
+		getLogger().Error("Failed to create auth middleware", zap.Error(err))
+		return
+	}
+
+	// 未登录用户按匿名评估，返回各特性的默认值
+	features := rg.Group("/features", authMiddleware.OptionalAuth())
+	{
+		features.GET("", featureFlagHandler.EvaluateFeatures)
+		features.GET("/:key", featureFlagHandler.EvaluateFeature)
+	}
+}
+
+// subscribeFeatureFlagInvalidation 任一实例修改或重新预热特性标记后清除本实例的特性标记缓存
+func subscribeFeatureFlagInvalidation(service featureflagsvc.FeatureFlagService) {
+	bus := cache.DefaultInvalidationBus()
+	if bus == nil {
+		return
+	}
+
+	bus.Subscribe(cache.InvalidationFeatureFlags, func(context.Context, *cache.Invalidation) {
+		service.Refresh()
+	})
+	bus.Subscribe(cache.InvalidationReferenceData, func(_ context.Context, msg *cache.Invalidation) {
+		if len(msg.Targets) == 0 || slices.Contains(msg.Targets, warmup.SourceFeatureFlags) {
+			service.Refresh()
+		}
+	})
+}
+
+// setupAdminCacheRoutes 设置参考数据缓存管理路由，启动时未创建预热器则不注册
+func setupAdminCacheRoutes(rg *gin.RouterGroup) {
+	warmer := warmup.Default()
+	if warmer == nil {
+		return
+	}
+
+	authMiddleware, err := middleware.NewAuthMiddleware(config.AppConfig.JWT.Secret, getLogger())
+	if err != nil {
+		getLogger().Error("Failed to create auth middleware", zap.Error(err))
+		return
+	}
+
+	warmupHandler := handlers.NewCacheWarmupHandler(warmer, cache.DefaultInvalidationBus(), getLogger())
+	warmupHandler.SetAuditService(auditsvc.Default())
+	admin := rg.Group("/admin/cache", authMiddleware.RequireAuth(), authMiddleware.RequireRole("admin"))
+	{
+		admin.POST("/warmup", warmupHandler.WarmCache)
+	}
+}
+
+// setupAdminMaintenanceRoutes 设置维护任务管理路由，启动时未创建维护任务调度器则不注册
+func setupAdminMaintenanceRoutes(rg *gin.RouterGroup) {
+	scheduler := maintenance.Default()
+	if scheduler == nil {
+		return
+	}
+
+	authMiddleware, err := middleware.NewAuthMiddleware(config.AppConfig.JWT.Secret, getLogger())
+	if err != nil {
+		getLogger().Error("Failed to create auth middleware", zap.Error(err))
+		return
+	}
+
+	maintenanceHandler := handlers.NewMaintenanceHandler(scheduler, getLogger())
+	admin := rg.Group("/admin/maintenance", authMiddleware.RequireAuth(), authMiddleware.RequireRole("admin"))
+	{
+		admin.GET("/tasks", maintenanceHandler.ListTasks)
+	}
+	rg.GET("/system/jobs", authMiddleware.RequireAuth(), authMiddleware.RequireRole("admin"), maintenanceHandler.ListJobs)
+}
+
+// setupStorageHealthRoutes 设置存储后端健康状况路由，启动时未启用存储健康检查则不注册
+func setupStorageHealthRoutes(rg *gin.RouterGroup) {
+	monitor := storage.DefaultHealthMonitor()
+	if monitor == nil {
+		return
+	}
+
+	authMiddleware, err := middleware.NewAuthMiddleware(config.AppConfig.JWT.Secret, getLogger())
+	if err != nil {
+		getLogger().Error("Failed to create auth middleware", zap.Error(err))
+		return
+	}
+
+	healthHandler := handlers.NewStorageHealthHandler(monitor, getLogger())
+	rg.GET("/system/storage", authMiddleware.RequireAuth(), authMiddleware.RequireRole("admin"), healthHandler.GetStorageHealth)
+}
+
+// setupAdminJobRoutes 设置后台任务队列管理路由，启动时未创建任务队列则不注册
+func setupAdminJobRoutes(rg *gin.RouterGroup) {
+	queue := jobs.Default()
+	if queue == nil {
+		return
+	}
+
+	authMiddleware, err := middleware.NewAuthMiddleware(config.AppConfig.JWT.Secret, getLogger())
+	if err != nil {
+		getLogger().Error("Failed to create auth middleware", zap.Error(err))
+		return
+	}
+
+	jobHandler := handlers.NewJobQueueHandler(queue, getLogger())
+	admin := rg.Group("/admin/jobs", authMiddleware.RequireAuth(), authMiddleware.RequireRole("admin"))
+	{
+		admin.GET("/pools", jobHandler.ListPools)
+	}
+}
+
+// setupAdminUploadRoutes 设置分片上传有效期指标路由，启动时未创建有效期调整器则不注册
+func setupAdminUploadRoutes(rg *gin.RouterGroup) {
+	tuner := filesvc.DefaultUploadTTLTuner()
+	if tuner == nil {
+		return
+	}
+
+	authMiddleware, err := middleware.NewAuthMiddleware(config.AppConfig.JWT.Secret, getLogger())
+	if err != nil {
+		getLogger().Error("Failed to create auth middleware", zap.Error(err))
+		return
+	}
+
+	ttlHandler := handlers.NewUploadTTLHandler(tuner, getLogger())
+	admin := rg.Group("/admin/uploads", authMiddleware.RequireAuth(), authMiddleware.RequireRole("admin"))
+	{
+		admin.GET("/ttl", ttlHandler.ListMetrics)
+	}
+}
+
+// setupAdminUserRoutes 设置用户安全管理路由，启动时未创建令牌吊销存储则不注册
+func setupAdminUserRoutes(rg *gin.RouterGroup) {
+	tokenStore := cache.DefaultTokenStore()
+	if tokenStore == nil {
+		return
+	}
+
+	authMiddleware, err := middleware.NewAuthMiddleware(config.AppConfig.JWT.Secret, getLogger())
+	if err != nil {
+		getLogger().Error("Failed to create auth middleware", zap.Error(err))
+		return
+	}
+
+	// 任务进度保存在进程内，同一服务实例需在路由间共享
+	resetService := user.NewPasswordResetService(
+		userrepo.NewUserRepository(database.GetDB()),
+		tokenStore,
+		cache.DefaultRefreshTokenStore(),
+		email.GlobalQueue{},
+		user.PasswordResetOptionsFromConfig(config.AppConfig.App.Name, config.AppConfig.Security.ForcedPasswordReset),
+		getLogger(),
+	)
+	resetHandler := handlers.NewAdminPasswordResetHandler(resetService, getLogger())
+	resetHandler.SetAuditService(auditsvc.Default())
+	// 不使用用户缓存，管理员修改的状态和配额立即对所有实例生效
+	userHandler := handlers.NewAdminUserHandler(
+		user.NewUserService(userrepo.NewUserRepository(database.GetDB()), nil, database.GetDB()),
+		resetService,
+		getLogger(),
+	)
+	userHandler.SetAuditService(auditsvc.Default())
+	userHandler.SetTokenStores(tokenStore, cache.DefaultRefreshTokenStore())
+	if config.AppConfig.User.Inactivity.Enabled {
+		userHandler.SetInactivityService(newInactivityService())
+	}
+	admin := rg.Group("/admin/users", authMiddleware.RequireAuth(), authMiddleware.RequireRole("admin"))
+	{
+		admin.GET("", userHandler.ListUsers)
+		admin.GET("/inactive", userHandler.ListInactiveUsers)
+		admin.GET("/:id", userHandler.GetUser)
+		admin.POST("/:id/suspend", userHandler.SuspendUser)
+		admin.POST("/:id/activate", userHandler.ActivateUser)
+		admin.POST("/:id/password-reset", userHandler.ResetUserPassword)
+		admin.PUT("/:id/quota", userHandler.UpdateUserQuota)
+		admin.POST("/password-resets", resetHandler.StartBulkPasswordReset)
+		admin.GET("/password-resets/:job_id", resetHandler.GetBulkPasswordReset)
+	}
+
+	impersonationCfg := config.AppConfig.Security.Impersonation
+	impersonationHandler, err := handlers.NewAdminImpersonationHandler(
+		userrepo.NewUserRepository(database.GetDB()),
+		config.AppConfig.JWT.Secret,
+		handlers.ImpersonationOptions{
+			DefaultTTL:    impersonationCfg.DefaultTTL,
+			MaxTTL:        impersonationCfg.MaxTTL,
+			ForceReadOnly: impersonationCfg.ForceReadOnly,
+		},
+		getLogger(),
+	)
+	if err != nil {
+		getLogger().Error("Failed to create impersonation handler", zap.Error(err))
+		return
+	}
+	impersonationHandler.SetAuditService(auditsvc.Default())
+	admin.POST("/:id/impersonate", impersonationHandler.ImpersonateUser)
+}
+
+// setupAdminAuditRoutes 设置管理员操作审计日志查询路由，启动时未创建审计服务则不注册
+func setupAdminAuditRoutes(rg *gin.RouterGroup) {
+	auditService := auditsvc.Default()
+	if auditService == nil {
+		return
+	}
+
+	authMiddleware, err := middleware.NewAuthMiddleware(config.AppConfig.JWT.Secret, getLogger())
+	if err != nil {
+		getLogger().Error("Failed to create auth middleware", zap.Error(err))
+		return
+	}
+
+	auditHandler := handlers.NewAdminAuditHandler(auditService, getLogger())
+	admin := rg.Group("/admin/audit-logs", authMiddleware.RequireAuth(), authMiddleware.RequireRole("admin"))
+	{
+		admin.GET("", auditHandler.ListLogs)
+	}
+}
+
+// setupAdminSecurityAuditRoutes 设置安全审计日志查询路由，启动时未创建安全审计服务则不注册
+func setupAdminSecurityAuditRoutes(rg *gin.RouterGroup) {
+	securityService := auditsvc.DefaultSecurity()
+	if securityService == nil {
+		return
+	}
+
+	authMiddleware, err := middleware.NewAuthMiddleware(config.AppConfig.JWT.Secret, getLogger())
+	if err != nil {
+		getLogger().Error("Failed to create auth middleware", zap.Error(err))
+		return
+	}
+
+	securityHandler := handlers.NewAdminSecurityAuditHandler(securityService, getLogger())
+	admin := rg.Group("/admin/security-logs", authMiddleware.RequireAuth(), authMiddleware.RequireRole("admin"))
+	{
+		admin.GET("", securityHandler.ListLogs)
+	}
+}
+
+// setupAdminSSORoutes 设置企业单点登录管理路由，启动时未启用单点登录则不注册
+func setupAdminSSORoutes(rg *gin.RouterGroup) {
+	ssoService := sso.Default()
+	if ssoService == nil {
+		return
+	}
+
+	authMiddleware, err := middleware.NewAuthMiddleware(config.AppConfig.JWT.Secret, getLogger())
+	if err != nil {
+		getLogger().Error("Failed to create auth middleware", zap.Error(err))
+		return
+	}
+
+	ssoHandler := handlers.NewAdminSSOHandler(ssoService, getLogger())
+	ssoHandler.SetAuditService(auditsvc.Default())
+	admin := rg.Group("/admin/sso/providers", authMiddleware.RequireAuth(), authMiddleware.RequireRole("admin"))
+	{
+		admin.GET("", ssoHandler.ListProviders)
+		admin.POST("", ssoHandler.CreateProvider)
+		admin.GET("/:id", ssoHandler.GetProvider)
+		admin.PUT("/:id", ssoHandler.UpdateProvider)
+		admin.DELETE("/:id", ssoHandler.DeleteProvider)
+		admin.POST("/:id/domains", ssoHandler.AddDomain)
+		admin.POST("/:id/domains/:domain_id/verify", ssoHandler.VerifyDomain)
+		admin.DELETE("/:id/domains/:domain_id", ssoHandler.DeleteDomain)
+	}
+
+	if scimService := sso.DefaultSCIM(); scimService != nil {
+		ssoHandler.SetSCIMService(scimService)
+		admin.GET("/:id/scim-token", ssoHandler.GetSCIMToken)
+		admin.POST("/:id/scim-token", ssoHandler.IssueSCIMToken)
+		admin.DELETE("/:id/scim-token", ssoHandler.RevokeSCIMToken)
+	}
+}
+
+// setupAdminEmailRoutes 设置邮件死信队列、发送线路统计和邮件模板管理路由，启动时未创建邮件服务则不注册
+func setupAdminEmailRoutes(rg *gin.RouterGroup) {
+	queue := email.GetGlobalDeadLetterQueue()
+	if queue == nil {
+		return
+	}
+
+	authMiddleware, err := middleware.NewAuthMiddleware(config.AppConfig.JWT.Secret, getLogger())
+	if err != nil {
+		getLogger().Error("Failed to create auth middleware", zap.Error(err))
+		return
+	}
+
+	emailHandler := handlers.NewAdminEmailHandler(queue, getLogger())
+	emailHandler.SetAuditService(auditsvc.Default())
+	emailHandler.SetRouteMonitor(email.GetGlobalRouteMonitor())
+	admin := rg.Group("/admin/email", authMiddleware.RequireAuth(), authMiddleware.RequireRole("admin"))
+	{
+		admin.GET("/queue", emailHandler.GetQueueStatus)
+		admin.GET("/routes", emailHandler.ListRoutes)
+		admin.GET("/dead-letters", emailHandler.ListDeadLetters)
+		admin.GET("/dead-letters/export", emailHandler.ExportDeadLetters)
+		admin.POST("/dead-letters/retry", emailHandler.RetryDeadLetters)
+		admin.POST("/dead-letters/purge", emailHandler.PurgeDeadLetters)
+	}
+
+	manager := email.GetGlobalTemplateManager()
+	if manager == nil {
+		return
+	}
+	templateHandler := handlers.NewAdminEmailTemplateHandler(manager, getLogger())
+	templateHandler.SetAuditService(auditsvc.Default())
+	{
+		admin.GET("/templates", templateHandler.ListTemplates)
+		admin.GET("/templates/:name/:language", templateHandler.GetTemplate)
+		admin.POST("/templates/:name/:language/preview", templateHandler.PreviewTemplate)
+		admin.PUT("/templates/:name/:language", templateHandler.UpdateTemplate)
+		admin.DELETE("/templates/:name/:language", templateHandler.ResetTemplate)
+	}
+}
+
+// setupAdminFileGrantRoutes 设置文件访问授权管理路由
+func setupAdminFileGrantRoutes(rg *gin.RouterGroup) {
+	authMiddleware, err := middleware.NewAuthMiddleware(config.AppConfig.JWT.Secret, getLogger())
+	if err != nil {
+		getLogger().Error("Failed to create auth middleware", zap.Error(err))
+		return
+	}
+
+	grantHandler := handlers.NewAdminFileGrantHandler(newPermissionService(), getLogger())
+	grantHandler.SetAuditService(auditsvc.Default())
+	grantHandler.SetSecurityAuditService(auditsvc.DefaultSecurity())
+	admin := rg.Group("/admin/files", authMiddleware.RequireAuth(), authMiddleware.RequireRole("admin"))
+	{
+		admin.GET("/:id/grants", grantHandler.ListGrants)
+		admin.PUT("/:id/grants", grantHandler.Grant)
+		admin.DELETE("/:id/grants/:grant_id", grantHandler.RevokeGrant)
+		admin.GET("/:id/permissions", grantHandler.GetEffectivePermissions)
+	}
+}
+
+// setupAdminFileTransferRoutes 设置管理员强制转移文件所有权路由
+func setupAdminFileTransferRoutes(rg *gin.RouterGroup) {
+	authMiddleware, err := middleware.NewAuthMiddleware(config.AppConfig.JWT.Secret, getLogger())
+	if err != nil {
+		getLogger().Error("Failed to create auth middleware", zap.Error(err))
+		return
+	}
+
+	transferHandler := handlers.NewAdminFileTransferHandler(newFileTransferService(), getLogger())
+	transferHandler.SetAuditService(auditsvc.Default())
+	transferHandler.SetSecurityAuditService(auditsvc.DefaultSecurity())
+	admin := rg.Group("/admin/transfers", authMiddleware.RequireAuth(), authMiddleware.RequireRole("admin"))
+	{
+		admin.POST("", transferHandler.ForceTransfer)
+	}
+}
+
+// setupAdminRetainedContentRoutes 设置删除后保留的存储对象管理路由，存储不可用时不注册
+func setupAdminRetainedContentRoutes(rg *gin.RouterGroup) {
+	store, err := storage.NewFromConfig(config.AppConfig.Storage)
+	if err != nil {
+		getLogger().Warn("Retained content management disabled: storage unavailable", zap.Error(err))
+		return
+	}
+	authMiddleware, err := middleware.NewAuthMiddleware(config.AppConfig.JWT.Secret, getLogger())
+	if err != nil {
+		getLogger().Error("Failed to create auth middleware", zap.Error(err))
+		return
+	}
+
+	retainedHandler := handlers.NewAdminRetainedContentHandler(newContentRetentionService(store), getLogger())
+	retainedHandler.SetAuditService(auditsvc.Default())
+	admin := rg.Group("/admin/retained-content", authMiddleware.RequireAuth(), authMiddleware.RequireRole("admin"))
+	{
+		admin.GET("", retainedHandler.ListRetained)
+		admin.POST("/:id/restore", retainedHandler.RestoreRetained)
+	}
+}
+
+// setupAdminFolderLimitRoutes 设置文件夹限制报告路由
+func setupAdminFolderLimitRoutes(rg *gin.RouterGroup) {
+	authMiddleware, err := middleware.NewAuthMiddleware(config.AppConfig.JWT.Secret, getLogger())
+	if err != nil {
+		getLogger().Error("Failed to create auth middleware", zap.Error(err))
+		return
+	}
+
+	limitHandler := handlers.NewAdminFolderLimitHandler(folderLimiter(), getLogger())
+	admin := rg.Group("/admin/folders", authMiddleware.RequireAuth(), authMiddleware.RequireRole("admin"))
+	{
+		admin.GET("/limits", limitHandler.GetFolderLimitReport)
+	}
+}
+
+// setupSCIMRoutes 设置企业目录同步(SCIM 2.0)路由，使用身份提供方的目录同步令牌认证，启动时未启用单点登录则不注册
+func setupSCIMRoutes(rg *gin.RouterGroup) {
+	scimService := sso.DefaultSCIM()
+	if scimService == nil {
+		return
+	}
+
+	scimHandler := handlers.NewSCIMHandler(scimService, getLogger())
+	scim := rg.Group("/scim/v2", scimHandler.RequireToken())
+	{
+		scim.GET("/ServiceProviderConfig", scimHandler.ServiceProviderConfig)
+		scim.GET("/Users", scimHandler.ListUsers)
+		scim.POST("/Users", scimHandler.CreateUser)
+		scim.GET("/Users/:id", scimHandler.GetUser)
+		scim.PUT("/Users/:id", scimHandler.ReplaceUser)
+		scim.PATCH("/Users/:id", scimHandler.PatchUser)
+		scim.DELETE("/Users/:id", scimHandler.DeleteUser)
+		scim.GET("/Groups", scimHandler.ListGroups)
+		scim.POST("/Groups", scimHandler.CreateGroup)
+		scim.GET("/Groups/:id", scimHandler.GetGroup)
+		scim.PUT("/Groups/:id", scimHandler.ReplaceGroup)
+		scim.PATCH("/Groups/:id", scimHandler.PatchGroup)
+		scim.DELETE("/Groups/:id", scimHandler.DeleteGroup)
+	}
+}
+
+// setupAdminSystemRoutes 设置系统配置查询路由
+func setupAdminSystemRoutes(rg *gin.RouterGroup) {
+	authMiddleware, err := middleware.NewAuthMiddleware(config.AppConfig.JWT.Secret, getLogger())
+	if err != nil {
+		getLogger().Error("Failed to create auth middleware", zap.Error(err))
+		return
+	}
+
+	logLevelHandler := handlers.NewAdminLogLevelHandler(logger.Levels, getLogger())
+	logLevelHandler.SetAuditService(auditsvc.Default())
+
+	admin := rg.Group("/admin/system", authMiddleware.RequireAuth(), authMiddleware.RequireRole("admin"))
+	{
+		admin.GET("/config", EffectiveConfigHandler)
+		admin.GET("/log-level", logLevelHandler.GetLogLevel)
+		admin.PUT("/log-level", logLevelHandler.UpdateLogLevel)
+	}
+}
+
+// setupTeamRoutes 设置团队相关路由
+func setupTeamRoutes(rg *gin.RouterGroup) {
+	authMiddleware, err := middleware.NewAuthMiddleware(config.AppConfig.JWT.Secret, getLogger())
+	if err != nil {
+		getLogger().Error("Failed to create auth middleware", zap.Error(err))
+		return
+	}
+
+	// 成员角色缓存在Redis中，Redis未初始化时每次权限检查都查询数据库
+	var membershipCache teamsvc.MembershipCache
+	if cache.RedisClient != nil {
+		membershipCache = cache.NewCacheManager()
+	}
+	teamService := teamsvc.NewTeamService(
+		teamrepo.NewTeamRepository(database.GetDB()),
+		userrepo.NewUserRepository(database.GetDB()),
+		filerepo.NewFileRepository(database.GetDB()),
+		membershipCache,
+		teamsvc.TeamOptionsFromConfig(config.AppConfig.Team),
+		getLogger(),
+	)
+	// 删除的团队超过恢复期限后由维护任务彻底删除
+	if scheduler := maintenance.Default(); scheduler != nil {
+		task := maintenance.Task{Name: maintenance.TaskArchivedTeams, Run: func(ctx context.Context) (int64, error) {
+			return teamService.PurgeArchived(ctx, scheduler.BatchSize())
+		}}
+		if err := scheduler.Register(task); err != nil {
+			getLogger().Warn("Failed to register archived team cleanup", zap.Error(err))
+		}
+	}
+	teamHandler := handlers.NewTeamHandler(teamService, getLogger())
+	teamHandler.SetSecurityAuditService(auditsvc.DefaultSecurity())
+
+	teams := rg.Group("/teams", authMiddleware.RequireAuth())
+	{
+		teams.GET("", teamHandler.ListTeams)
+		teams.POST("", teamHandler.CreateTeam)
+		teams.GET("/:id", teamHandler.GetTeam)
+		teams.PATCH("/:id", teamHandler.UpdateTeam)
+		teams.DELETE("/:id", teamHandler.DeleteTeam)
+		teams.POST("/:id/restore", teamHandler.RestoreTeam)
+
+		teams.GET("/:id/members", teamHandler.ListMembers)
+		teams.POST("/:id/members", teamHandler.InviteMember)
+		teams.PUT("/:id/members/:user_id/role", teamHandler.UpdateMemberRole)
+		teams.DELETE("/:id/members/:user_id", teamHandler.RemoveMember)
+
+		teams.GET("/:id/files", teamHandler.ListFiles)
+		teams.POST("/:id/files", teamHandler.ShareFile)
+		teams.DELETE("/:id/files/:file_id", teamHandler.RemoveFile)
 	}
 }
 
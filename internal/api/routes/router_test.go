@@ -6,11 +6,16 @@ import (
 	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	"cloudpan/internal/pkg/buildinfo"
 	"cloudpan/internal/pkg/config"
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/service/readonly"
 )
 
 func TestMain(m *testing.M) {
@@ -90,6 +95,26 @@ func TestDatabaseHealthHandler(t *testing.T) {
 	})
 }
 
+func TestReadinessHandler(t *testing.T) {
+	readonly.SetDefault(readonly.NewMode(true, nil, nil))
+	defer readonly.SetDefault(nil)
+	router := SetupRouter()
+
+	req := httptest.NewRequest("GET", "/readyz", nil)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	// 数据库可能未连接，但应返回只读模式状态
+	assert.True(t, recorder.Code == http.StatusOK || recorder.Code == http.StatusServiceUnavailable)
+
+	var response map[string]interface{}
+	err := json.Unmarshal(recorder.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "read_only", response["mode"])
+	assert.Equal(t, true, response["read_only"])
+	assert.Equal(t, readonly.SourceConfig, response["read_only_source"])
+}
+
 func TestSystemStatsHandler(t *testing.T) {
 	router := SetupRouter()
 
@@ -114,9 +139,29 @@ func TestSystemStatsHandler(t *testing.T) {
 		assert.NotNil(t, data["server"])
 		assert.NotNil(t, data["database"])
 		assert.NotZero(t, data["timestamp"])
+		application := data["application"].(map[string]interface{})
+		assert.NotNil(t, application["build"])
 	})
 }
 
+func TestBuildInfoHandler(t *testing.T) {
+	router := SetupRouter()
+
+	req := httptest.NewRequest("GET", "/api/v1/version", nil)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	var response struct {
+		Data buildinfo.Info `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.Equal(t, buildinfo.Get(), response.Data)
+	assert.NotEmpty(t, response.Data.Version)
+	assert.NotEmpty(t, response.Data.GoVersion)
+}
+
 func TestAPIVersionRoutes(t *testing.T) {
 	router := SetupRouter()
 
@@ -239,11 +284,11 @@ func TestBusinessRoutes(t *testing.T) {
 	})
 
 	t.Run("TestFileRoutes", func(t *testing.T) {
-		// 测试文件列表
+		// 测试文件列表（需要认证，存储不可用时不注册，应该返回401或404）
 		req := httptest.NewRequest("GET", "/api/v1/files", nil)
 		recorder := httptest.NewRecorder()
 		router.ServeHTTP(recorder, req)
-		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.True(t, recorder.Code == http.StatusNotFound || recorder.Code == http.StatusUnauthorized)
 
 		// 测试文件上传
 		req = httptest.NewRequest("POST", "/api/v1/files/upload", nil)
@@ -251,25 +296,25 @@ func TestBusinessRoutes(t *testing.T) {
 		router.ServeHTTP(recorder, req)
 		assert.Equal(t, http.StatusOK, recorder.Code)
 
-		// 测试文件下载
+		// 测试文件下载（需要认证，应该返回401或404）
 		req = httptest.NewRequest("GET", "/api/v1/files/123/download", nil)
 		recorder = httptest.NewRecorder()
 		router.ServeHTTP(recorder, req)
-		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.True(t, recorder.Code == http.StatusNotFound || recorder.Code == http.StatusUnauthorized)
 	})
 
 	t.Run("TestTeamRoutes", func(t *testing.T) {
-		// 测试团队列表
+		// 测试团队列表（需要认证，未配置JWT密钥时不注册，应该返回401或404）
 		req := httptest.NewRequest("GET", "/api/v1/teams", nil)
 		recorder := httptest.NewRecorder()
 		router.ServeHTTP(recorder, req)
-		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.True(t, recorder.Code == http.StatusNotFound || recorder.Code == http.StatusUnauthorized)
 
-		// 测试创建团队
+		// 测试创建团队（需要认证）
 		req = httptest.NewRequest("POST", "/api/v1/teams", nil)
 		recorder = httptest.NewRecorder()
 		router.ServeHTTP(recorder, req)
-		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.True(t, recorder.Code == http.StatusNotFound || recorder.Code == http.StatusUnauthorized)
 	})
 
 	t.Run("TestMessageRoutes", func(t *testing.T) {
@@ -322,3 +367,133 @@ func TestMiddlewareIntegration(t *testing.T) {
 		assert.Equal(t, "en-US", recorder.Header().Get("Content-Language"))
 	})
 }
+
+func TestPProfRoutes(t *testing.T) {
+	const secret = "test-secret-key-for-pprof-routes-32b"
+	original := config.AppConfig
+	defer func() { config.AppConfig = original }()
+
+	jwtManager, err := utils.NewDefaultJWTManager(secret)
+	require.NoError(t, err)
+	userToken, err := jwtManager.GenerateAccessToken(1, "user", "user@example.com", "user")
+	require.NoError(t, err)
+	adminToken, err := jwtManager.GenerateAccessToken(2, "admin", "admin@example.com", "admin")
+	require.NoError(t, err)
+
+	request := func(router *gin.Engine, path, token string) int {
+		req := httptest.NewRequest("GET", path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	t.Run("disabled by default", func(t *testing.T) {
+		config.AppConfig = &config.Config{JWT: config.JWTConfig{Secret: secret}}
+		router := gin.New()
+		setupPProfRoutes(router)
+		assert.Equal(t, http.StatusNotFound, request(router, "/debug/pprof/heap", adminToken))
+	})
+
+	t.Run("admin only", func(t *testing.T) {
+		config.AppConfig = &config.Config{
+			JWT:        config.JWTConfig{Secret: secret},
+			Monitoring: config.MonitoringConfig{PProf: config.PProfConfig{Enabled: true}},
+		}
+		router := gin.New()
+		setupPProfRoutes(router)
+
+		assert.Equal(t, http.StatusUnauthorized, request(router, "/debug/pprof/heap", ""))
+		assert.Equal(t, http.StatusForbidden, request(router, "/debug/pprof/heap", userToken))
+		assert.Equal(t, http.StatusOK, request(router, "/debug/pprof/goroutine?debug=1", adminToken))
+		assert.Equal(t, http.StatusOK, request(router, "/debug/pprof/", adminToken))
+	})
+}
+
+func TestAdminSystemConfigRoute(t *testing.T) {
+	const secret = "test-secret-key-for-config-routes-32b"
+	original := config.AppConfig
+	defer func() { config.AppConfig = original }()
+	config.AppConfig = &config.Config{
+		App: config.App{Name: "cloudpan"},
+		JWT: config.JWTConfig{Secret: secret},
+		Database: config.DatabaseConfig{
+			MySQL: config.MySQLConfig{Password: "db-password"},
+		},
+	}
+
+	jwtManager, err := utils.NewDefaultJWTManager(secret)
+	require.NoError(t, err)
+	userToken, err := jwtManager.GenerateAccessToken(1, "user", "user@example.com", "user")
+	require.NoError(t, err)
+	adminToken, err := jwtManager.GenerateAccessToken(2, "admin", "admin@example.com", "admin")
+	require.NoError(t, err)
+
+	router := gin.New()
+	setupAdminSystemRoutes(router.Group("/api/v1"))
+	request := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/v1/admin/system/config", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, request("").Code)
+	assert.Equal(t, http.StatusForbidden, request(userToken).Code)
+
+	recorder := request(adminToken)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.NotContains(t, recorder.Body.String(), "db-password")
+	assert.NotContains(t, recorder.Body.String(), secret)
+
+	var response struct {
+		Data config.EffectiveConfig `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.Equal(t, "cloudpan", response.Data.Section("app")["name"])
+	assert.Equal(t, config.SourceDefault, response.Data.Sources["app.name"])
+}
+
+func TestSetupRouter_CORSAndSecurityHeaders(t *testing.T) {
+	original := config.AppConfig
+	defer func() { config.AppConfig = original }()
+	allowCredentials := false
+	config.AppConfig = &config.Config{
+		App: config.App{Name: "cloudpan"},
+		Security: config.SecurityConfig{
+			CORS: config.CORSConfig{
+				AllowOrigins:     []string{"https://app.example.com"},
+				AllowCredentials: &allowCredentials,
+				MaxAge:           600,
+			},
+			Headers: config.HeadersConfig{Enabled: true, HSTSMaxAge: time.Hour},
+		},
+	}
+	router := SetupRouter()
+
+	request := func(method, origin string) http.Header {
+		req := httptest.NewRequest(method, "/health", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("X-Forwarded-Proto", "https")
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder.Header()
+	}
+
+	header := request(http.MethodOptions, "https://app.example.com")
+	assert.Equal(t, "https://app.example.com", header.Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, header.Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, "600", header.Get("Access-Control-Max-Age"))
+	assert.Contains(t, header.Get("Access-Control-Allow-Headers"), "X-API-Key")
+
+	header = request(http.MethodGet, "https://other.example.com")
+	assert.Empty(t, header.Get("Access-Control-Allow-Origin"), "未配置的源")
+	assert.Equal(t, "nosniff", header.Get("X-Content-Type-Options"))
+	assert.Contains(t, header.Get("Content-Security-Policy"), "frame-ancestors 'none'")
+	assert.Equal(t, "max-age=3600", header.Get("Strict-Transport-Security"))
+}
@@ -0,0 +1,188 @@
+// Package cli 云盘命令行客户端
+//
+// 基于 pkg/client SDK 实现登录、浏览、复制移动删除、递归上传下载(支持续传)和创建分享，
+// 适用于无图形界面的服务器和需要脚本化操作的用户。登录状态保存在配置目录，访问令牌过期时自动刷新
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"cloudpan/pkg/client"
+)
+
+// ErrUsage 命令参数错误，已向用户输出用法
+var ErrUsage = errors.New("参数错误")
+
+// command 子命令
+type command struct {
+	usage       string
+	summary     string
+	requireAuth bool
+	run         func(a *App, ctx context.Context, args []string) error
+}
+
+// commands 全部子命令，在init中注册以避免与子命令实现之间的初始化循环
+var commands map[string]*command
+
+func init() {
+	commands = map[string]*command{
+		"login":    {usage: "login [-server URL] [-u 用户名] [-p 密码]", summary: "使用账号密码登录，未指定密码时从标准输入读取", run: (*App).runLogin},
+		"logout":   {usage: "logout", summary: "登出并清除本地登录状态", requireAuth: true, run: (*App).runLogout},
+		"ls":       {usage: "ls [-l] [远程路径]", summary: "列出文件夹内容", requireAuth: true, run: (*App).runList},
+		"mkdir":    {usage: "mkdir [-p] 远程路径", summary: "新建文件夹，-p 时同时创建不存在的上级文件夹", requireAuth: true, run: (*App).runMkdir},
+		"cp":       {usage: "cp 远程源路径 远程目标路径", summary: "复制文件或文件夹", requireAuth: true, run: (*App).runCopy},
+		"mv":       {usage: "mv 远程源路径 远程目标路径", summary: "移动或重命名文件或文件夹", requireAuth: true, run: (*App).runMove},
+		"rm":       {usage: "rm 远程路径...", summary: "将文件或文件夹移入回收站", requireAuth: true, run: (*App).runRemove},
+		"upload":   {usage: "upload [-r] 本地路径... 远程文件夹", summary: "分片上传文件，-r 递归上传文件夹，中断后重新执行即可续传", requireAuth: true, run: (*App).runUpload},
+		"download": {usage: "download [-r] 远程路径 本地路径", summary: "下载文件，-r 递归下载文件夹，已下载部分自动续传", requireAuth: true, run: (*App).runDownload},
+		"share":    {usage: "share [-password 密码] [-expire-days 天数] [-max-downloads 次数] [-view-only] 远程路径", summary: "创建分享链接", requireAuth: true, run: (*App).runShare},
+	}
+}
+
+// App 命令行客户端
+type App struct {
+	Stdout     io.Writer    // 标准输出
+	Stderr     io.Writer    // 错误和进度输出
+	Stdin      io.Reader    // 读取密码
+	ConfigDir  string       // 配置目录，保存登录状态和上传续传记录
+	HTTPClient *http.Client // 为nil时使用http.DefaultClient
+
+	client *client.Client
+	creds  *Credentials
+}
+
+// NewApp 创建使用标准输入输出和默认配置目录的命令行客户端
+func NewApp() *App {
+	return &App{
+		Stdout:    os.Stdout,
+		Stderr:    os.Stderr,
+		Stdin:     os.Stdin,
+		ConfigDir: DefaultConfigDir(),
+	}
+}
+
+// Run 执行子命令
+//
+// 访问令牌过期时使用刷新令牌换取新令牌并重试一次；上传和下载重试时从中断处续传
+func (a *App) Run(ctx context.Context, args []string) error {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		a.printUsage()
+		if len(args) == 0 {
+			return ErrUsage
+		}
+		return nil
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(a.Stderr, "未知命令: %s\n\n", args[0])
+		a.printUsage()
+		return ErrUsage
+	}
+
+	creds, err := a.loadCredentials()
+	if err != nil {
+		return err
+	}
+	a.creds = creds
+	if cmd.requireAuth && creds.AccessToken == "" {
+		return fmt.Errorf("尚未登录，请先执行 login")
+	}
+	a.client = client.NewClient(creds.Server, a.HTTPClient)
+	a.client.SetTokens(creds.AccessToken, creds.RefreshToken)
+
+	err = cmd.run(a, ctx, args[1:])
+	if cmd.requireAuth && isUnauthorized(err) && creds.RefreshToken != "" {
+		if refreshErr := a.refresh(ctx); refreshErr != nil {
+			return fmt.Errorf("登录已过期，请重新执行 login: %w", refreshErr)
+		}
+		err = cmd.run(a, ctx, args[1:])
+	}
+	return err
+}
+
+// refresh 刷新令牌并保存
+func (a *App) refresh(ctx context.Context) error {
+	if _, err := a.client.Refresh(ctx); err != nil {
+		return err
+	}
+	a.creds.AccessToken, a.creds.RefreshToken = a.client.Tokens()
+	return a.saveCredentials(a.creds)
+}
+
+// printUsage 输出全部子命令的用法
+func (a *App) printUsage() {
+	fmt.Fprintln(a.Stderr, "用法: cloudpan-cli <命令> [参数]")
+	fmt.Fprintln(a.Stderr)
+	fmt.Fprintln(a.Stderr, "命令:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(a.Stderr, "  %-10s %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintln(a.Stderr)
+	fmt.Fprintln(a.Stderr, "远程路径以 / 开头，如 /docs/report.pdf。执行 cloudpan-cli <命令> -h 查看命令参数")
+}
+
+// newFlagSet 创建子命令参数解析器，解析失败时输出命令用法
+func (a *App) newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(a.Stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(a.Stderr, "用法: cloudpan-cli %s\n", commands[name].usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseFlags 解析子命令参数并检查位置参数数量，max为-1表示不限制
+func (a *App) parseFlags(fs *flag.FlagSet, args []string, min, max int) ([]string, error) {
+	if err := fs.Parse(args); err != nil {
+		return nil, ErrUsage
+	}
+	rest := fs.Args()
+	if len(rest) < min || (max >= 0 && len(rest) > max) {
+		fs.Usage()
+		return nil, ErrUsage
+	}
+	return rest, nil
+}
+
+// isUnauthorized 检查错误是否为访问令牌无效或过期
+func isUnauthorized(err error) bool {
+	var apiErr *client.APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized
+}
+
+// readLine 从输入读取一行，去除首尾空白
+func readLine(r io.Reader) (string, error) {
+	var line strings.Builder
+	buf := make([]byte, 1)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			if buf[0] == '\n' {
+				break
+			}
+			line.WriteByte(buf[0])
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) && line.Len() > 0 {
+				break
+			}
+			return "", err
+		}
+	}
+	return strings.TrimSpace(line.String()), nil
+}
@@ -0,0 +1,395 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"cloudpan/pkg/client"
+)
+
+// fakeServer 内存中的云盘服务，只实现命令行客户端用到的接口
+type fakeServer struct {
+	t  *testing.T
+	mu sync.Mutex
+
+	accessToken string
+	nextID      uint
+	files       map[uint]*client.File
+	contents    map[uint][]byte
+	upload      *client.UploadSession
+	chunks      map[int][]byte
+	uploadName  string
+	uploadTo    *uint
+	initiated   int
+	ranges      []string
+	refreshes   int
+	shares      []client.CreateShareRequest
+}
+
+func newFakeServer(t *testing.T) (*fakeServer, *httptest.Server) {
+	s := &fakeServer{t: t, accessToken: "access-1", nextID: 1, files: map[uint]*client.File{}, contents: map[uint][]byte{}}
+	server := httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(server.Close)
+	return s, server
+}
+
+// respond 按服务端统一响应结构写入响应
+func respond(w http.ResponseWriter, status int, message string, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	code := status
+	if status == http.StatusOK {
+		code = client.CodeSuccess
+	}
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"code": code, "message": message, "data": data})
+}
+
+func (s *fakeServer) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch r.URL.Path {
+	case "/api/v1/auth/login":
+		var req client.LoginRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if req.Identifier != "alice" || req.Password != "secret" {
+			respond(w, http.StatusUnauthorized, "用户名或密码错误", nil)
+			return
+		}
+		respond(w, http.StatusOK, "ok", client.Session{AccessToken: s.accessToken, RefreshToken: "refresh-1", User: &client.User{Username: "alice"}})
+		return
+	case "/api/v1/auth/refresh":
+		s.refreshes++
+		s.accessToken = fmt.Sprintf("access-%d", s.refreshes+1)
+		respond(w, http.StatusOK, "ok", client.Session{AccessToken: s.accessToken, RefreshToken: "refresh-2"})
+		return
+	}
+
+	if r.Header.Get("Authorization") != "Bearer "+s.accessToken {
+		respond(w, http.StatusUnauthorized, "访问令牌无效或已过期", nil)
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v1/"), "/")
+	switch {
+	case r.URL.Path == "/api/v1/files" && r.Method == http.MethodGet:
+		respond(w, http.StatusOK, "ok", s.list(parseParent(r.URL.Query().Get("parent_id"))))
+	case r.URL.Path == "/api/v1/files/folders":
+		var req struct {
+			Name     string `json:"name"`
+			ParentID *uint  `json:"parent_id"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		respond(w, http.StatusOK, "ok", s.add(req.ParentID, req.Name, true, nil))
+	case r.URL.Path == "/api/v1/files/uploads":
+		var req client.UploadRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		s.initiated++
+		s.upload = &client.UploadSession{UploadID: "u1", Name: req.Name, Size: req.Size, ChunkSize: 4, ChunkHashAlgorithm: client.ChunkHashMD5}
+		s.upload.TotalChunks = int((req.Size + 3) / 4)
+		s.chunks, s.uploadName, s.uploadTo = map[int][]byte{}, req.Name, req.ParentID
+		respond(w, http.StatusOK, "ok", s.uploadState())
+	case len(parts) >= 3 && parts[1] == "uploads":
+		s.serveUpload(w, r, parts[2:])
+	case r.URL.Path == "/api/v1/shares":
+		var req client.CreateShareRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		s.shares = append(s.shares, req)
+		respond(w, http.StatusOK, "ok", client.Share{ID: 1, FileID: req.FileID, ShareCode: "abc123", ShareURL: "/api/v1/public/shares/abc123"})
+	case parts[0] == "files" && len(parts) >= 2:
+		s.serveFile(w, r, parts[1:])
+	default:
+		respond(w, http.StatusNotFound, "not found", nil)
+	}
+}
+
+func (s *fakeServer) serveUpload(w http.ResponseWriter, r *http.Request, parts []string) {
+	if s.upload == nil || parts[0] != s.upload.UploadID {
+		respond(w, http.StatusNotFound, "上传任务不存在", nil)
+		return
+	}
+	switch {
+	case len(parts) == 1:
+		respond(w, http.StatusOK, "ok", s.uploadState())
+	case parts[1] == "chunks":
+		index, _ := strconv.Atoi(parts[2])
+		data, _ := io.ReadAll(r.Body)
+		s.chunks[index] = data
+		respond(w, http.StatusOK, "ok", s.uploadState())
+	case parts[1] == "merge":
+		var content []byte
+		for i := 0; i < s.upload.TotalChunks; i++ {
+			content = append(content, s.chunks[i]...)
+		}
+		s.upload = nil
+		respond(w, http.StatusOK, "ok", s.add(s.uploadTo, s.uploadName, false, content))
+	}
+}
+
+func (s *fakeServer) serveFile(w http.ResponseWriter, r *http.Request, parts []string) {
+	id, _ := strconv.ParseUint(parts[0], 10, 64)
+	file, ok := s.files[uint(id)]
+	if !ok {
+		respond(w, http.StatusNotFound, "文件不存在", nil)
+		return
+	}
+	var body struct {
+		Name     string `json:"name"`
+		ParentID *uint  `json:"parent_id"`
+	}
+	if r.Body != nil {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+	}
+
+	action := ""
+	if len(parts) > 1 {
+		action = parts[1]
+	}
+	switch action {
+	case "":
+		file.Name = body.Name
+		respond(w, http.StatusOK, "ok", file)
+	case "move":
+		file.ParentID, file.Path = body.ParentID, s.pathOf(body.ParentID)
+		respond(w, http.StatusOK, "ok", file)
+	case "copy":
+		respond(w, http.StatusOK, "ok", s.add(body.ParentID, file.Name, file.IsFolder, s.contents[file.ID]))
+	case "trash":
+		delete(s.files, file.ID)
+		respond(w, http.StatusOK, "ok", client.TrashItem{FileID: file.ID, OriginalPath: file.FullPath()})
+	case "download":
+		content := s.contents[file.ID]
+		if rng := r.Header.Get("Range"); rng != "" {
+			s.ranges = append(s.ranges, rng)
+			offset, _ := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(rng, "bytes="), "-"))
+			w.WriteHeader(http.StatusPartialContent)
+			_, _ = w.Write(content[offset:])
+			return
+		}
+		_, _ = w.Write(content)
+	}
+}
+
+// add 新建文件或文件夹
+func (s *fakeServer) add(parentID *uint, name string, isFolder bool, content []byte) *client.File {
+	file := &client.File{ID: s.nextID, ParentID: parentID, Name: name, Path: s.pathOf(parentID), IsFolder: isFolder, Size: int64(len(content))}
+	s.nextID++
+	s.files[file.ID] = file
+	s.contents[file.ID] = content
+	return file
+}
+
+func (s *fakeServer) pathOf(parentID *uint) string {
+	if parentID == nil {
+		return "/"
+	}
+	return s.files[*parentID].FullPath()
+}
+
+func (s *fakeServer) list(parentID *uint) []client.File {
+	files := []client.File{}
+	for _, file := range s.files {
+		if sameFolder(file.ParentID, parentID) {
+			files = append(files, *file)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].ID < files[j].ID })
+	return files
+}
+
+func (s *fakeServer) uploadState() *client.UploadSession {
+	session := *s.upload
+	session.UploadedChunks = []int{}
+	for index := range s.chunks {
+		session.UploadedChunks = append(session.UploadedChunks, index)
+	}
+	return &session
+}
+
+// find 按完整路径查找文件
+func (s *fakeServer) find(fullPath string) *client.File {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, file := range s.files {
+		if file.FullPath() == fullPath {
+			return file
+		}
+	}
+	return nil
+}
+
+func parseParent(value string) *uint {
+	if value == "" {
+		return nil
+	}
+	id, _ := strconv.ParseUint(value, 10, 64)
+	parent := uint(id)
+	return &parent
+}
+
+// newTestApp 创建使用临时配置目录且已登录的客户端
+func newTestApp(t *testing.T, server *httptest.Server) (*App, *bytes.Buffer) {
+	stdout := &bytes.Buffer{}
+	app := &App{Stdout: stdout, Stderr: io.Discard, Stdin: strings.NewReader(""), ConfigDir: t.TempDir()}
+	require.NoError(t, app.saveCredentials(&Credentials{Server: server.URL, AccessToken: "access-1", RefreshToken: "refresh-1"}))
+	return app, stdout
+}
+
+func TestApp_Login(t *testing.T) {
+	_, server := newFakeServer(t)
+	app := &App{Stdout: &bytes.Buffer{}, Stderr: io.Discard, Stdin: strings.NewReader("secret\n"), ConfigDir: t.TempDir()}
+
+	require.NoError(t, app.Run(context.Background(), []string{"login", "-server", server.URL, "-u", "alice"}))
+
+	creds, err := app.loadCredentials()
+	require.NoError(t, err)
+	assert.Equal(t, &Credentials{Server: server.URL, AccessToken: "access-1", RefreshToken: "refresh-1", Username: "alice"}, creds)
+
+	info, err := os.Stat(app.credentialsPath())
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+}
+
+func TestApp_RequiresLogin(t *testing.T) {
+	app := &App{Stdout: io.Discard, Stderr: io.Discard, ConfigDir: t.TempDir()}
+
+	err := app.Run(context.Background(), []string{"ls"})
+	assert.ErrorContains(t, err, "尚未登录")
+	assert.ErrorIs(t, app.Run(context.Background(), []string{"unknown"}), ErrUsage)
+}
+
+func TestApp_RefreshesExpiredToken(t *testing.T) {
+	fake, server := newFakeServer(t)
+	app, stdout := newTestApp(t, server)
+	fake.accessToken = "rotated"
+
+	require.NoError(t, app.Run(context.Background(), []string{"mkdir", "/docs"}))
+	require.NoError(t, app.Run(context.Background(), []string{"ls"}))
+
+	assert.Equal(t, 1, fake.refreshes)
+	assert.Equal(t, "docs/\n", stdout.String())
+	creds, err := app.loadCredentials()
+	require.NoError(t, err)
+	assert.Equal(t, "access-2", creds.AccessToken)
+	assert.Equal(t, "refresh-2", creds.RefreshToken)
+}
+
+func TestApp_MkdirMoveCopyRemove(t *testing.T) {
+	fake, server := newFakeServer(t)
+	app, _ := newTestApp(t, server)
+	ctx := context.Background()
+
+	require.NoError(t, app.Run(ctx, []string{"mkdir", "-p", "/a/b"}))
+	require.NotNil(t, fake.find("/a/b"))
+	assert.ErrorContains(t, app.Run(ctx, []string{"mkdir", "/x/y"}), "远程路径不存在")
+
+	// 目标是已存在的文件夹时移入其中，否则按最后一级重命名
+	require.NoError(t, app.Run(ctx, []string{"mkdir", "/c"}))
+	require.NoError(t, app.Run(ctx, []string{"mv", "/c", "/a"}))
+	require.NotNil(t, fake.find("/a/c"))
+	require.NoError(t, app.Run(ctx, []string{"mv", "/a/c", "/a/b/d"}))
+	require.NotNil(t, fake.find("/a/b/d"))
+
+	require.NoError(t, app.Run(ctx, []string{"cp", "/a/b", "/copy"}))
+	assert.NotNil(t, fake.find("/copy"))
+	assert.NotNil(t, fake.find("/a/b"))
+
+	require.NoError(t, app.Run(ctx, []string{"rm", "/copy"}))
+	assert.Nil(t, fake.find("/copy"))
+	assert.ErrorContains(t, app.Run(ctx, []string{"rm", "/"}), "根目录")
+}
+
+func TestApp_UploadAndDownload(t *testing.T) {
+	fake, server := newFakeServer(t)
+	app, _ := newTestApp(t, server)
+	ctx := context.Background()
+
+	local := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(local, "photos", "2024"), 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(local, "photos", "a.txt"), []byte("hello world"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(local, "photos", "2024", "b.txt"), []byte("bye"), 0o600))
+
+	assert.ErrorContains(t, app.Run(ctx, []string{"upload", filepath.Join(local, "photos"), "/"}), "-r")
+	require.NoError(t, app.Run(ctx, []string{"upload", "-r", filepath.Join(local, "photos"), "/"}))
+
+	uploaded := fake.find("/photos/a.txt")
+	require.NotNil(t, uploaded)
+	assert.Equal(t, []byte("hello world"), fake.contents[uploaded.ID])
+	require.NotNil(t, fake.find("/photos/2024/b.txt"))
+
+	state := uploadState{}
+	require.NoError(t, readJSON(app.uploadStatePath(), &state))
+	assert.Empty(t, state)
+
+	// 已存在的同名同大小文件跳过
+	require.NoError(t, app.Run(ctx, []string{"upload", "-r", filepath.Join(local, "photos"), "/"}))
+	assert.Len(t, fake.list(nil), 1)
+
+	// 本地已下载部分时从断点续传
+	out := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(out, "photos"), 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(out, "photos", "a.txt"), []byte("hello"), 0o600))
+	require.NoError(t, app.Run(ctx, []string{"download", "-r", "/photos", out}))
+
+	data, err := os.ReadFile(filepath.Join(out, "photos", "a.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", string(data))
+	data, err = os.ReadFile(filepath.Join(out, "photos", "2024", "b.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "bye", string(data))
+	assert.Equal(t, []string{"bytes=5-"}, fake.ranges)
+}
+
+func TestApp_UploadResumesSavedSession(t *testing.T) {
+	fake, server := newFakeServer(t)
+	app, _ := newTestApp(t, server)
+	ctx := context.Background()
+
+	local := filepath.Join(t.TempDir(), "a.txt")
+	require.NoError(t, os.WriteFile(local, []byte("hello world"), 0o600))
+	info, err := os.Stat(local)
+	require.NoError(t, err)
+
+	// 模拟上次上传在第一个分片后中断
+	fake.upload = &client.UploadSession{UploadID: "u1", Name: "a.txt", Size: 11, ChunkSize: 4, TotalChunks: 3, ChunkHashAlgorithm: client.ChunkHashMD5}
+	fake.chunks, fake.uploadName = map[int][]byte{0: []byte("hell")}, "a.txt"
+	require.NoError(t, writeJSON(app.uploadStatePath(), uploadState{uploadKey(local, info, nil): "u1"}))
+
+	require.NoError(t, app.Run(ctx, []string{"upload", local, "/"}))
+
+	uploaded := fake.find("/a.txt")
+	require.NotNil(t, uploaded)
+	assert.Equal(t, "hello world", string(fake.contents[uploaded.ID]))
+	assert.Zero(t, fake.initiated)
+}
+
+func TestApp_Share(t *testing.T) {
+	fake, server := newFakeServer(t)
+	app, stdout := newTestApp(t, server)
+	ctx := context.Background()
+	require.NoError(t, app.Run(ctx, []string{"mkdir", "/docs"}))
+
+	require.NoError(t, app.Run(ctx, []string{"share", "-password", "1234", "-expire-days", "7", "-view-only", "/docs"}))
+
+	require.Len(t, fake.shares, 1)
+	assert.Equal(t, "view", fake.shares[0].Permission)
+	assert.Equal(t, "1234", fake.shares[0].Password)
+	assert.Equal(t, 7, fake.shares[0].ExpireDays)
+	assert.Nil(t, fake.shares[0].MaxDownload)
+	assert.Equal(t, server.URL+"/api/v1/public/shares/abc123\n", stdout.String())
+}
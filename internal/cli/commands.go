@@ -0,0 +1,312 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"cloudpan/pkg/client"
+)
+
+// runLogin 使用账号密码登录并保存登录状态
+func (a *App) runLogin(ctx context.Context, args []string) error {
+	fs := a.newFlagSet("login")
+	server := fs.String("server", firstNonEmpty(os.Getenv("CLOUDPAN_SERVER"), a.creds.Server, "http://localhost:8080"), "服务地址(默认 $CLOUDPAN_SERVER)")
+	identifier := fs.String("u", a.creds.Username, "用户名、邮箱或手机号")
+	password := fs.String("p", os.Getenv("CLOUDPAN_PASSWORD"), "密码(默认 $CLOUDPAN_PASSWORD，为空时从标准输入读取)")
+	if _, err := a.parseFlags(fs, args, 0, 0); err != nil {
+		return err
+	}
+
+	var err error
+	if *identifier == "" {
+		fmt.Fprint(a.Stderr, "用户名: ")
+		if *identifier, err = readLine(a.Stdin); err != nil {
+			return fmt.Errorf("读取用户名失败: %w", err)
+		}
+	}
+	if *password == "" {
+		fmt.Fprint(a.Stderr, "密码: ")
+		if *password, err = readLine(a.Stdin); err != nil {
+			return fmt.Errorf("读取密码失败: %w", err)
+		}
+	}
+
+	a.client = client.NewClient(*server, a.HTTPClient)
+	session, err := a.client.Login(ctx, *identifier, *password)
+	if err != nil {
+		return fmt.Errorf("登录失败: %w", err)
+	}
+
+	creds := &Credentials{
+		Server:       strings.TrimRight(*server, "/"),
+		AccessToken:  session.AccessToken,
+		RefreshToken: session.RefreshToken,
+		Username:     *identifier,
+	}
+	if session.User != nil && session.User.Username != "" {
+		creds.Username = session.User.Username
+	}
+	if err := a.saveCredentials(creds); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.Stdout, "已登录 %s (%s)\n", creds.Server, creds.Username)
+	return nil
+}
+
+// runLogout 吊销刷新令牌并清除本地登录状态
+func (a *App) runLogout(ctx context.Context, args []string) error {
+	if _, err := a.parseFlags(a.newFlagSet("logout"), args, 0, 0); err != nil {
+		return err
+	}
+	if err := a.client.Logout(ctx); err != nil && !isUnauthorized(err) {
+		return fmt.Errorf("登出失败: %w", err)
+	}
+	if err := os.Remove(a.credentialsPath()); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("清除登录状态失败: %w", err)
+	}
+	fmt.Fprintln(a.Stdout, "已登出")
+	return nil
+}
+
+// runList 列出文件夹内容，目标是文件时只输出该文件
+func (a *App) runList(ctx context.Context, args []string) error {
+	fs := a.newFlagSet("ls")
+	long := fs.Bool("l", false, "显示类型、大小和修改时间")
+	rest, err := a.parseFlags(fs, args, 0, 1)
+	if err != nil {
+		return err
+	}
+	remote := "/"
+	if len(rest) == 1 {
+		remote = rest[0]
+	}
+
+	target, err := a.resolve(ctx, remote)
+	if err != nil {
+		return err
+	}
+	files := []client.File{}
+	if target != nil && !target.IsFolder {
+		files = append(files, *target)
+	} else if files, err = a.client.ListAllFiles(ctx, folderID(target)); err != nil {
+		return err
+	}
+
+	if !*long {
+		for _, file := range files {
+			fmt.Fprintln(a.Stdout, displayName(&file))
+		}
+		return nil
+	}
+	w := tabwriter.NewWriter(a.Stdout, 0, 0, 2, ' ', 0)
+	for _, file := range files {
+		kind, size := "file", formatSize(file.Size)
+		if file.IsFolder {
+			kind, size = "dir", "-"
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\n", kind, file.ID, size, file.UpdatedAt.Local().Format("2006-01-02 15:04"), displayName(&file))
+	}
+	return w.Flush()
+}
+
+// runMkdir 新建文件夹
+func (a *App) runMkdir(ctx context.Context, args []string) error {
+	fs := a.newFlagSet("mkdir")
+	parents := fs.Bool("p", false, "同时创建不存在的上级文件夹，文件夹已存在时不报错")
+	rest, err := a.parseFlags(fs, args, 1, -1)
+	if err != nil {
+		return err
+	}
+
+	for _, remote := range rest {
+		if *parents {
+			if _, err := a.ensureFolder(ctx, remote); err != nil {
+				return err
+			}
+			continue
+		}
+		parentID, name, err := a.resolveTarget(ctx, remote)
+		if err != nil {
+			return err
+		}
+		if name == "" {
+			return fmt.Errorf("已存在: %s", cleanRemote(remote))
+		}
+		if _, err := a.client.CreateFolder(ctx, parentID, name); err != nil {
+			return fmt.Errorf("新建文件夹 %s 失败: %w", cleanRemote(remote), err)
+		}
+	}
+	return nil
+}
+
+// runCopy 复制文件或文件夹，目标不存在时以其最后一级作为副本名称
+func (a *App) runCopy(ctx context.Context, args []string) error {
+	rest, err := a.parseFlags(a.newFlagSet("cp"), args, 2, 2)
+	if err != nil {
+		return err
+	}
+	source, parentID, name, err := a.resolveTransfer(ctx, rest[0], rest[1])
+	if err != nil {
+		return err
+	}
+
+	copied, err := a.client.CopyFile(ctx, source.ID, parentID)
+	if err != nil {
+		return fmt.Errorf("复制失败: %w", err)
+	}
+	if name != "" && copied.Name != name {
+		if copied, err = a.client.RenameFile(ctx, copied.ID, name); err != nil {
+			return fmt.Errorf("副本重命名失败: %w", err)
+		}
+	}
+	fmt.Fprintln(a.Stdout, copied.FullPath())
+	return nil
+}
+
+// runMove 移动或重命名文件或文件夹
+func (a *App) runMove(ctx context.Context, args []string) error {
+	rest, err := a.parseFlags(a.newFlagSet("mv"), args, 2, 2)
+	if err != nil {
+		return err
+	}
+	source, parentID, name, err := a.resolveTransfer(ctx, rest[0], rest[1])
+	if err != nil {
+		return err
+	}
+
+	moved := source
+	if !sameFolder(source.ParentID, parentID) {
+		if moved, err = a.client.MoveFile(ctx, source.ID, parentID); err != nil {
+			return fmt.Errorf("移动失败: %w", err)
+		}
+	}
+	if name != "" && moved.Name != name {
+		if moved, err = a.client.RenameFile(ctx, source.ID, name); err != nil {
+			return fmt.Errorf("重命名失败: %w", err)
+		}
+	}
+	fmt.Fprintln(a.Stdout, moved.FullPath())
+	return nil
+}
+
+// runRemove 将文件或文件夹移入回收站
+func (a *App) runRemove(ctx context.Context, args []string) error {
+	rest, err := a.parseFlags(a.newFlagSet("rm"), args, 1, -1)
+	if err != nil {
+		return err
+	}
+
+	for _, remote := range rest {
+		target, err := a.resolve(ctx, remote)
+		if err != nil {
+			return err
+		}
+		if target == nil {
+			return fmt.Errorf("不能删除根目录")
+		}
+		item, err := a.client.TrashFile(ctx, target.ID)
+		if err != nil {
+			return fmt.Errorf("删除 %s 失败: %w", cleanRemote(remote), err)
+		}
+		fmt.Fprintf(a.Stdout, "已移入回收站: %s (将于 %s 自动删除)\n", item.OriginalPath, item.AutoDeleteAt.Local().Format("2006-01-02"))
+	}
+	return nil
+}
+
+// runShare 创建分享链接
+func (a *App) runShare(ctx context.Context, args []string) error {
+	fs := a.newFlagSet("share")
+	password := fs.String("password", "", "分享密码")
+	expireDays := fs.Int("expire-days", 0, "有效天数，0表示永久有效")
+	maxDownloads := fs.Int("max-downloads", 0, "最大下载次数，0表示不限制")
+	viewOnly := fs.Bool("view-only", false, "只允许查看，不允许下载")
+	rest, err := a.parseFlags(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	target, err := a.resolve(ctx, rest[0])
+	if err != nil {
+		return err
+	}
+	if target == nil {
+		return fmt.Errorf("不能分享根目录")
+	}
+
+	req := &client.CreateShareRequest{FileID: target.ID, Password: *password, ExpireDays: *expireDays}
+	if *maxDownloads > 0 {
+		req.MaxDownload = maxDownloads
+	}
+	if *viewOnly {
+		req.Permission = "view"
+	}
+	share, err := a.client.CreateShare(ctx, req)
+	if err != nil {
+		return fmt.Errorf("创建分享失败: %w", err)
+	}
+
+	fmt.Fprintln(a.Stdout, a.client.ShareLink(share))
+	if share.ExpiresAt != nil {
+		fmt.Fprintf(a.Stderr, "有效期至 %s\n", share.ExpiresAt.Local().Format("2006-01-02 15:04"))
+	}
+	return nil
+}
+
+// resolveTransfer 解析复制或移动的源文件和目标位置
+func (a *App) resolveTransfer(ctx context.Context, src, dst string) (*client.File, *uint, string, error) {
+	source, err := a.resolve(ctx, src)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if source == nil {
+		return nil, nil, "", fmt.Errorf("不能复制或移动根目录")
+	}
+	parentID, name, err := a.resolveTarget(ctx, dst)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return source, parentID, name, nil
+}
+
+// sameFolder 比较两个父文件夹ID，nil表示根目录
+func sameFolder(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// displayName 文件夹名称后追加 /
+func displayName(file *client.File) string {
+	if file.IsFolder {
+		return file.Name + "/"
+	}
+	return file.Name
+}
+
+// formatSize 以易读的单位显示文件大小
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%dB", size)
+	}
+	value, exp := float64(size)/unit, 0
+	for value >= unit && exp < 4 {
+		value /= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%c", value, "KMGTP"[exp])
+}
+
+// firstNonEmpty 返回第一个非空字符串
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Credentials 保存在本地的登录状态
+type Credentials struct {
+	Server       string `json:"server"`        // 服务地址
+	AccessToken  string `json:"access_token"`  // 访问令牌
+	RefreshToken string `json:"refresh_token"` // 刷新令牌
+	Username     string `json:"username"`      // 登录用户名，仅用于展示
+}
+
+// DefaultConfigDir 返回默认配置目录($XDG_CONFIG_HOME/cloudpan 或 ~/.config/cloudpan)
+func DefaultConfigDir() string {
+	if dir, err := os.UserConfigDir(); err == nil {
+		return filepath.Join(dir, "cloudpan")
+	}
+	return ".cloudpan"
+}
+
+// credentialsPath 登录状态文件路径
+func (a *App) credentialsPath() string {
+	return filepath.Join(a.ConfigDir, "credentials.json")
+}
+
+// loadCredentials 读取登录状态，文件不存在时返回空状态
+func (a *App) loadCredentials() (*Credentials, error) {
+	creds := &Credentials{}
+	if err := readJSON(a.credentialsPath(), creds); err != nil {
+		return nil, fmt.Errorf("读取登录状态失败: %w", err)
+	}
+	return creds, nil
+}
+
+// saveCredentials 保存登录状态，文件仅当前用户可读写
+func (a *App) saveCredentials(creds *Credentials) error {
+	if err := writeJSON(a.credentialsPath(), creds); err != nil {
+		return fmt.Errorf("保存登录状态失败: %w", err)
+	}
+	return nil
+}
+
+// readJSON 读取JSON文件，文件不存在时保持out不变
+func readJSON(path string, out interface{}) error {
+	data, err := os.ReadFile(path) // #nosec G304 - 配置目录下的固定文件
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// writeJSON 原子地写入JSON文件(先写临时文件再重命名)
+func writeJSON(path string, value interface{}) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"cloudpan/pkg/client"
+)
+
+// cleanRemote 规范化远程路径，总是以 / 开头且不以 / 结尾(根目录除外)
+func cleanRemote(remote string) string {
+	return path.Clean("/" + strings.TrimSpace(remote))
+}
+
+// splitRemote 将远程路径拆分为各级名称，根目录返回空
+func splitRemote(remote string) []string {
+	remote = cleanRemote(remote)
+	if remote == "/" {
+		return nil
+	}
+	return strings.Split(strings.TrimPrefix(remote, "/"), "/")
+}
+
+// resolve 按路径逐级查找远程文件，根目录返回nil
+func (a *App) resolve(ctx context.Context, remote string) (*client.File, error) {
+	var current *client.File
+	for _, name := range splitRemote(remote) {
+		child, err := a.findChild(ctx, current, name)
+		if err != nil {
+			return nil, err
+		}
+		if child == nil {
+			return nil, fmt.Errorf("远程路径不存在: %s", cleanRemote(remote))
+		}
+		current = child
+	}
+	return current, nil
+}
+
+// resolveFolder 查找远程文件夹，返回其ID，根目录返回nil
+func (a *App) resolveFolder(ctx context.Context, remote string) (*uint, error) {
+	folder, err := a.resolve(ctx, remote)
+	if err != nil {
+		return nil, err
+	}
+	if folder == nil {
+		return nil, nil
+	}
+	if !folder.IsFolder {
+		return nil, fmt.Errorf("不是文件夹: %s", cleanRemote(remote))
+	}
+	return &folder.ID, nil
+}
+
+// findChild 在文件夹中按名称查找子项，不存在时返回nil
+func (a *App) findChild(ctx context.Context, parent *client.File, name string) (*client.File, error) {
+	if parent != nil && !parent.IsFolder {
+		return nil, fmt.Errorf("不是文件夹: %s", parent.FullPath())
+	}
+	files, err := a.client.ListAllFiles(ctx, folderID(parent))
+	if err != nil {
+		return nil, err
+	}
+	for i := range files {
+		if files[i].Name == name {
+			return &files[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// ensureFolder 逐级查找远程文件夹，不存在的文件夹自动创建
+func (a *App) ensureFolder(ctx context.Context, remote string) (*uint, error) {
+	var current *client.File
+	for _, name := range splitRemote(remote) {
+		child, err := a.findChild(ctx, current, name)
+		if err != nil {
+			return nil, err
+		}
+		if child == nil {
+			child, err = a.client.CreateFolder(ctx, folderID(current), name)
+			if err != nil {
+				return nil, fmt.Errorf("创建文件夹 %s 失败: %w", name, err)
+			}
+		}
+		current = child
+	}
+	return folderID(current), nil
+}
+
+// resolveTarget 解析复制或移动的目标
+//
+// 目标是已存在的文件夹时放入该文件夹并保留原名；目标不存在时放入其上级文件夹并以最后一级作为新名称
+func (a *App) resolveTarget(ctx context.Context, remote string) (parentID *uint, name string, err error) {
+	target, err := a.resolve(ctx, remote)
+	if err == nil {
+		if target != nil && !target.IsFolder {
+			return nil, "", fmt.Errorf("目标已存在: %s", cleanRemote(remote))
+		}
+		return folderID(target), "", nil
+	}
+
+	parentID, parentErr := a.resolveFolder(ctx, path.Dir(cleanRemote(remote)))
+	if parentErr != nil {
+		return nil, "", parentErr
+	}
+	return parentID, path.Base(cleanRemote(remote)), nil
+}
+
+// folderID 返回文件夹ID，nil表示根目录
+func folderID(folder *client.File) *uint {
+	if folder == nil {
+		return nil
+	}
+	id := folder.ID
+	return &id
+}
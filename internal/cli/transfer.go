@@ -0,0 +1,336 @@
+package cli
+
+import (
+	"context"
+	"crypto/md5" // #nosec G501 - 与服务端文件哈希算法一致，仅用于完整性校验
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"cloudpan/pkg/client"
+)
+
+// uploadState 未完成的上传任务，键为本地文件标识，值为上传任务ID
+type uploadState map[string]string
+
+// uploadStatePath 上传续传记录文件路径
+func (a *App) uploadStatePath() string {
+	return filepath.Join(a.ConfigDir, "uploads.json")
+}
+
+// uploadKey 本地文件和目标位置的标识，文件被修改后标识随之变化，不会续传到旧任务
+func uploadKey(absPath string, info os.FileInfo, parentID *uint) string {
+	parent := "root"
+	if parentID != nil {
+		parent = strconv.FormatUint(uint64(*parentID), 10)
+	}
+	return fmt.Sprintf("%s|%d|%d|%s", absPath, info.Size(), info.ModTime().UnixNano(), parent)
+}
+
+// uploader 一次upload命令的执行状态
+type uploader struct {
+	app      *App
+	state    uploadState
+	children map[string][]client.File // 已列出的远程文件夹内容，键为文件夹ID
+}
+
+// runUpload 分片上传本地文件或文件夹到远程文件夹
+func (a *App) runUpload(ctx context.Context, args []string) error {
+	fs := a.newFlagSet("upload")
+	recursive := fs.Bool("r", false, "递归上传文件夹")
+	rest, err := a.parseFlags(fs, args, 2, -1)
+	if err != nil {
+		return err
+	}
+	sources, remote := rest[:len(rest)-1], rest[len(rest)-1]
+
+	parentID, err := a.resolveFolder(ctx, remote)
+	if err != nil {
+		return err
+	}
+
+	u := &uploader{app: a, state: uploadState{}, children: map[string][]client.File{}}
+	if err := readJSON(a.uploadStatePath(), &u.state); err != nil {
+		return fmt.Errorf("读取上传续传记录失败: %w", err)
+	}
+
+	for _, source := range sources {
+		info, err := os.Stat(source)
+		if err != nil {
+			return fmt.Errorf("读取本地文件失败: %w", err)
+		}
+		if info.IsDir() {
+			if !*recursive {
+				return fmt.Errorf("%s 是文件夹，请使用 -r 递归上传", source)
+			}
+			err = u.uploadDir(ctx, source, parentID)
+		} else {
+			err = u.uploadFile(ctx, source, info, parentID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// uploadDir 在远程创建同名文件夹并递归上传其内容
+func (u *uploader) uploadDir(ctx context.Context, dir string, parentID *uint) error {
+	folder, err := u.child(ctx, parentID, filepath.Base(dir))
+	if err != nil {
+		return err
+	}
+	if folder == nil {
+		if folder, err = u.app.client.CreateFolder(ctx, parentID, filepath.Base(dir)); err != nil {
+			return fmt.Errorf("创建文件夹 %s 失败: %w", filepath.Base(dir), err)
+		}
+		u.forget(parentID)
+	} else if !folder.IsFolder {
+		return fmt.Errorf("远程已存在同名文件: %s", folder.FullPath())
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("读取本地文件夹失败: %w", err)
+	}
+	for _, entry := range entries {
+		local := filepath.Join(dir, entry.Name())
+		if entry.IsDir() {
+			err = u.uploadDir(ctx, local, &folder.ID)
+		} else if entry.Type().IsRegular() {
+			var info os.FileInfo
+			if info, err = entry.Info(); err == nil {
+				err = u.uploadFile(ctx, local, info, &folder.ID)
+			}
+		} else {
+			fmt.Fprintf(u.app.Stderr, "跳过非普通文件: %s\n", local)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// uploadFile 上传单个文件
+//
+// 远程已有同名同大小的文件时跳过；存在未完成的上传任务时续传，任务已失效则重新上传
+func (u *uploader) uploadFile(ctx context.Context, local string, info os.FileInfo, parentID *uint) error {
+	existing, err := u.child(ctx, parentID, info.Name())
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		if !existing.IsFolder && existing.Size == info.Size() {
+			fmt.Fprintf(u.app.Stderr, "已存在，跳过: %s\n", existing.FullPath())
+			return nil
+		}
+		return fmt.Errorf("远程已存在同名文件: %s", existing.FullPath())
+	}
+
+	absPath, err := filepath.Abs(local)
+	if err != nil {
+		return err
+	}
+	key := uploadKey(absPath, info, parentID)
+	opts := &client.UploadOptions{
+		ParentID: parentID,
+		ResumeID: u.state[key],
+		OnSession: func(session *client.UploadSession) {
+			u.state[key] = session.UploadID
+			if err := writeJSON(u.app.uploadStatePath(), u.state); err != nil {
+				fmt.Fprintf(u.app.Stderr, "保存上传续传记录失败: %v\n", err)
+			}
+		},
+		OnProgress: func(uploaded, total int64) {
+			fmt.Fprintf(u.app.Stderr, "\r%s %s/%s", info.Name(), formatSize(uploaded), formatSize(total))
+		},
+	}
+
+	file, err := u.app.client.UploadFile(ctx, local, opts)
+	if err != nil && opts.ResumeID != "" && isStaleUpload(err) {
+		fmt.Fprintf(u.app.Stderr, "上传任务已失效，重新上传: %s\n", local)
+		opts.ResumeID = ""
+		file, err = u.app.client.UploadFile(ctx, local, opts)
+	}
+	if err != nil {
+		fmt.Fprintln(u.app.Stderr)
+		return fmt.Errorf("上传 %s 失败: %w", local, err)
+	}
+
+	delete(u.state, key)
+	if err := writeJSON(u.app.uploadStatePath(), u.state); err != nil {
+		fmt.Fprintf(u.app.Stderr, "保存上传续传记录失败: %v\n", err)
+	}
+	u.forget(parentID)
+	fmt.Fprintf(u.app.Stderr, "\r")
+	fmt.Fprintln(u.app.Stdout, file.FullPath())
+	return nil
+}
+
+// child 在远程文件夹中按名称查找子项，文件夹内容在本次上传中缓存
+func (u *uploader) child(ctx context.Context, parentID *uint, name string) (*client.File, error) {
+	key := folderKey(parentID)
+	files, ok := u.children[key]
+	if !ok {
+		var err error
+		if files, err = u.app.client.ListAllFiles(ctx, parentID); err != nil {
+			return nil, err
+		}
+		u.children[key] = files
+	}
+	for i := range files {
+		if files[i].Name == name {
+			return &files[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// forget 文件夹内容变化后清除缓存
+func (u *uploader) forget(parentID *uint) {
+	delete(u.children, folderKey(parentID))
+}
+
+// folderKey 文件夹缓存键
+func folderKey(parentID *uint) string {
+	if parentID == nil {
+		return "root"
+	}
+	return strconv.FormatUint(uint64(*parentID), 10)
+}
+
+// isStaleUpload 检查续传失败是否因为上传任务已过期、已完成或不属于当前文件
+func isStaleUpload(err error) bool {
+	var apiErr *client.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == 404 || apiErr.StatusCode == 409 || apiErr.StatusCode == 410
+	}
+	return false
+}
+
+// runDownload 下载远程文件或文件夹
+func (a *App) runDownload(ctx context.Context, args []string) error {
+	fs := a.newFlagSet("download")
+	recursive := fs.Bool("r", false, "递归下载文件夹")
+	rest, err := a.parseFlags(fs, args, 2, 2)
+	if err != nil {
+		return err
+	}
+
+	source, err := a.resolve(ctx, rest[0])
+	if err != nil {
+		return err
+	}
+	if (source == nil || source.IsFolder) && !*recursive {
+		return fmt.Errorf("%s 是文件夹，请使用 -r 递归下载", cleanRemote(rest[0]))
+	}
+
+	local := rest[1]
+	if info, err := os.Stat(local); err == nil && info.IsDir() && source != nil {
+		local = filepath.Join(local, source.Name)
+	}
+	if source == nil || source.IsFolder {
+		return a.downloadDir(ctx, folderID(source), local)
+	}
+	return a.downloadFile(ctx, source, local)
+}
+
+// downloadDir 递归下载文件夹内容到本地目录
+func (a *App) downloadDir(ctx context.Context, folderID *uint, local string) error {
+	if err := os.MkdirAll(local, 0o750); err != nil {
+		return fmt.Errorf("创建本地文件夹失败: %w", err)
+	}
+	files, err := a.client.ListAllFiles(ctx, folderID)
+	if err != nil {
+		return err
+	}
+	for i := range files {
+		target := filepath.Join(local, files[i].Name)
+		if files[i].IsFolder {
+			err = a.downloadDir(ctx, &files[i].ID, target)
+		} else {
+			err = a.downloadFile(ctx, &files[i], target)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// downloadFile 下载单个文件
+//
+// 本地文件与远程大小一致时跳过，较小时从已有长度处续传，较大时重新下载；
+// 远程文件带MD5哈希时下载完成后校验，校验失败删除本地文件
+func (a *App) downloadFile(ctx context.Context, file *client.File, local string) error {
+	var offset int64
+	if info, err := os.Stat(local); err == nil {
+		if info.IsDir() {
+			return fmt.Errorf("本地已存在同名文件夹: %s", local)
+		}
+		if info.Size() == file.Size {
+			fmt.Fprintf(a.Stderr, "已存在，跳过: %s\n", local)
+			return nil
+		}
+		if info.Size() < file.Size {
+			offset = info.Size()
+		}
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if offset > 0 {
+		flags = os.O_WRONLY | os.O_APPEND
+	}
+	out, err := os.OpenFile(local, flags, 0o640) // #nosec G304 - 由用户指定的下载目标
+	if err != nil {
+		return fmt.Errorf("创建本地文件失败: %w", err)
+	}
+	defer out.Close()
+
+	if file.Size > offset {
+		body, err := a.client.OpenDownload(ctx, file.ID, offset)
+		if err != nil {
+			return fmt.Errorf("下载 %s 失败: %w", file.FullPath(), err)
+		}
+		defer body.Close()
+		if _, err := io.Copy(out, body); err != nil {
+			return fmt.Errorf("下载 %s 失败，重新执行即可续传: %w", file.FullPath(), err)
+		}
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("写入本地文件失败: %w", err)
+	}
+
+	if err := verifyDownload(file, local); err != nil {
+		_ = os.Remove(local)
+		return err
+	}
+	fmt.Fprintln(a.Stdout, local)
+	return nil
+}
+
+// verifyDownload 远程文件带MD5哈希时校验本地文件
+func verifyDownload(file *client.File, local string) error {
+	if file.Hash == nil || *file.Hash == "" || (file.HashType != nil && *file.HashType != "" && *file.HashType != "md5") {
+		return nil
+	}
+	f, err := os.Open(local) // #nosec G304 - 刚下载的本地文件
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	sum := md5.New() // #nosec G401 - 完整性校验
+	if _, err := io.Copy(sum, f); err != nil {
+		return err
+	}
+	if hex.EncodeToString(sum.Sum(nil)) != *file.Hash {
+		return fmt.Errorf("%s 校验失败，已删除本地文件，请重新下载", local)
+	}
+	return nil
+}
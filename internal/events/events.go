@@ -0,0 +1,122 @@
+// Package events 领域事件定义
+//
+// Webhook、WebSocket推送、发件箱和审计共用同一套事件契约：每种事件的载荷是带版本的结构体，
+// 字段只增不改；需要删除或修改字段时定义新版本(如 FileUploadedV2)，旧版本保留到所有消费方迁移完成。
+// 事件以 Envelope 传输，消费方按 type 和 version 解码，未知版本返回 ErrUnknownEvent。
+// 各事件的JSON Schema由 Schema 从结构体生成，cmd/eventschema 将其导出给外部消费方。
+//
+// 使用示例：
+//
+//	envelope, err := events.NewEnvelope(events.NewFileUploadedV1(file, now), idgen.Default().NewID(), now)
+//	body, err := json.Marshal(envelope)
+//
+//	event, err := envelope.Decode()
+//	switch e := event.(type) {
+//	case *events.FileUploadedV1:
+//		...
+//	}
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// 事件类型
+const (
+	TypeFileUploaded  = "file.uploaded"  // 文件上传完成
+	TypeShareCreated  = "share.created"  // 创建分享
+	TypeUserSuspended = "user.suspended" // 用户被停用
+)
+
+// ErrUnknownEvent 未定义的事件类型或版本
+var ErrUnknownEvent = errors.New("unknown event type or version")
+
+// Event 事件载荷，每个版本对应一个结构体
+type Event interface {
+	EventType() string
+	EventVersion() int
+}
+
+// Definition 已定义的事件版本
+type Definition struct {
+	Type        string       // 事件类型
+	Version     int          // 载荷版本
+	Description string       // 事件说明，写入JSON Schema
+	New         func() Event // 创建空载荷，用于解码
+}
+
+// definitions 所有事件版本，新增版本时在此登记
+var definitions = []Definition{
+	{Type: TypeFileUploaded, Version: 1, Description: "文件上传完成", New: func() Event { return &FileUploadedV1{} }},
+	{Type: TypeShareCreated, Version: 1, Description: "创建分享", New: func() Event { return &ShareCreatedV1{} }},
+	{Type: TypeUserSuspended, Version: 1, Description: "用户被停用", New: func() Event { return &UserSuspendedV1{} }},
+}
+
+// Definitions 返回所有事件版本，按类型和版本排序
+func Definitions() []Definition {
+	result := append([]Definition(nil), definitions...)
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Type != result[j].Type {
+			return result[i].Type < result[j].Type
+		}
+		return result[i].Version < result[j].Version
+	})
+	return result
+}
+
+// Lookup 查找事件版本
+func Lookup(eventType string, version int) (Definition, bool) {
+	for _, definition := range definitions {
+		if definition.Type == eventType && definition.Version == version {
+			return definition, true
+		}
+	}
+	return Definition{}, false
+}
+
+// Envelope 事件信封，所有传输方式使用相同的外层结构
+type Envelope struct {
+	ID         string          `json:"id"`          // 事件ID，消费方据此去重
+	Type       string          `json:"type"`        // 事件类型
+	Version    int             `json:"version"`     // 载荷版本
+	OccurredAt time.Time       `json:"occurred_at"` // 事件发生时间(UTC)
+	Data       json.RawMessage `json:"data"`        // 事件载荷
+}
+
+// NewEnvelope 将事件载荷封装为信封
+func NewEnvelope(event Event, id string, occurredAt time.Time) (*Envelope, error) {
+	if event == nil {
+		return nil, errors.New("event is nil")
+	}
+	if _, ok := Lookup(event.EventType(), event.EventVersion()); !ok {
+		return nil, fmt.Errorf("%w: %s v%d", ErrUnknownEvent, event.EventType(), event.EventVersion())
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("encode %s v%d: %w", event.EventType(), event.EventVersion(), err)
+	}
+	return &Envelope{
+		ID:         id,
+		Type:       event.EventType(),
+		Version:    event.EventVersion(),
+		OccurredAt: occurredAt.UTC(),
+		Data:       data,
+	}, nil
+}
+
+// Decode 按类型和版本解码事件载荷，返回对应版本结构体的指针
+func (e *Envelope) Decode() (Event, error) {
+	definition, ok := Lookup(e.Type, e.Version)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s v%d", ErrUnknownEvent, e.Type, e.Version)
+	}
+	event := definition.New()
+	if err := json.Unmarshal(e.Data, event); err != nil {
+		return nil, fmt.Errorf("decode %s v%d: %w", e.Type, e.Version, err)
+	}
+	return event, nil
+}
@@ -0,0 +1,77 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"cloudpan/internal/repository/models"
+)
+
+func TestEnvelope_RoundTrip(t *testing.T) {
+	now := time.Date(2026, 5, 1, 8, 0, 0, 0, time.FixedZone("CST", 8*3600))
+	mimeType := "application/pdf"
+	parentID := uint(7)
+	file := &models.File{UUID: "file-uuid", UserID: 3, ParentID: &parentID, Name: "report.pdf", Path: "/docs", Size: 1024, MimeType: &mimeType}
+	file.ID = 42
+
+	envelope, err := NewEnvelope(NewFileUploadedV1(file, now), "evt-000001", now)
+	require.NoError(t, err)
+	assert.Equal(t, TypeFileUploaded, envelope.Type)
+	assert.Equal(t, 1, envelope.Version)
+	assert.Equal(t, time.UTC, envelope.OccurredAt.Location())
+
+	body, err := json.Marshal(envelope)
+	require.NoError(t, err)
+	var received Envelope
+	require.NoError(t, json.Unmarshal(body, &received))
+
+	event, err := received.Decode()
+	require.NoError(t, err)
+	uploaded, ok := event.(*FileUploadedV1)
+	require.True(t, ok)
+	assert.Equal(t, uint(42), uploaded.FileID)
+	assert.Equal(t, uint(3), uploaded.OwnerID)
+	assert.Equal(t, &parentID, uploaded.ParentID)
+	assert.Equal(t, "application/pdf", uploaded.MimeType)
+	assert.Empty(t, uploaded.Hash)
+	assert.True(t, now.Equal(uploaded.UploadedAt))
+}
+
+func TestEnvelope_UnknownVersion(t *testing.T) {
+	envelope := &Envelope{Type: TypeShareCreated, Version: 99, Data: json.RawMessage(`{}`)}
+	_, err := envelope.Decode()
+	assert.True(t, errors.Is(err, ErrUnknownEvent))
+
+	_, err = NewEnvelope(nil, "evt", time.Now())
+	assert.Error(t, err)
+}
+
+func TestNewShareCreatedV1_OmitsSecrets(t *testing.T) {
+	password := "$2a$10$hash"
+	share := &models.FileShare{FileID: 5, SharerID: 3, ShareCode: "s0000001", Permission: "download", Password: &password, HasPassword: true}
+	share.ID = 9
+
+	body, err := json.Marshal(NewShareCreatedV1(share))
+	require.NoError(t, err)
+	assert.NotContains(t, string(body), password)
+	assert.Contains(t, string(body), `"has_password":true`)
+	assert.NotContains(t, string(body), "expires_at")
+}
+
+func TestDefinitions(t *testing.T) {
+	seen := map[string]bool{}
+	for _, definition := range Definitions() {
+		event := definition.New()
+		assert.Equal(t, definition.Type, event.EventType())
+		assert.Equal(t, definition.Version, event.EventVersion())
+		key := SchemaID(definition.Type, definition.Version)
+		assert.False(t, seen[key], "重复登记 %s", key)
+		seen[key] = true
+	}
+	assert.Len(t, seen, 3)
+}
@@ -0,0 +1,114 @@
+package events
+
+import (
+	"time"
+
+	"cloudpan/internal/repository/models"
+)
+
+// 载荷字段的 description 标签写入JSON Schema；可选字段使用指针或 omitempty，其余字段在Schema中为必填
+
+// FileUploadedV1 文件上传完成事件(v1)
+type FileUploadedV1 struct {
+	FileID     uint      `json:"file_id" description:"文件ID"`
+	FileUUID   string    `json:"file_uuid" description:"文件唯一标识符"`
+	OwnerID    uint      `json:"owner_id" description:"所属用户ID"`
+	ParentID   *uint     `json:"parent_id,omitempty" description:"所在文件夹ID，根目录时省略"`
+	Name       string    `json:"name" description:"文件名"`
+	Path       string    `json:"path" description:"文件完整路径"`
+	Size       int64     `json:"size" description:"文件大小(字节)"`
+	MimeType   string    `json:"mime_type,omitempty" description:"MIME类型"`
+	Hash       string    `json:"hash,omitempty" description:"文件哈希值"`
+	UploadedAt time.Time `json:"uploaded_at" description:"上传完成时间"`
+}
+
+// EventType 事件类型
+func (FileUploadedV1) EventType() string { return TypeFileUploaded }
+
+// EventVersion 载荷版本
+func (FileUploadedV1) EventVersion() int { return 1 }
+
+// NewFileUploadedV1 从文件记录构建上传完成事件
+func NewFileUploadedV1(file *models.File, uploadedAt time.Time) *FileUploadedV1 {
+	event := &FileUploadedV1{
+		FileID:     file.ID,
+		FileUUID:   file.UUID,
+		OwnerID:    file.UserID,
+		ParentID:   file.ParentID,
+		Name:       file.Name,
+		Path:       file.GetFullPath(),
+		Size:       file.Size,
+		UploadedAt: uploadedAt.UTC(),
+	}
+	if file.MimeType != nil {
+		event.MimeType = *file.MimeType
+	}
+	if file.Hash != nil {
+		event.Hash = *file.Hash
+	}
+	return event
+}
+
+// ShareCreatedV1 创建分享事件(v1)
+//
+// 不包含分享密码和提取链接中的敏感参数，消费方需要访问分享时通过API获取
+type ShareCreatedV1 struct {
+	ShareID     uint       `json:"share_id" description:"分享ID"`
+	ShareCode   string     `json:"share_code" description:"分享码"`
+	FileID      uint       `json:"file_id" description:"分享的文件ID"`
+	SharerID    uint       `json:"sharer_id" description:"分享者ID"`
+	Permission  string     `json:"permission" description:"权限类型" enum:"view,download,edit"`
+	HasPassword bool       `json:"has_password" description:"是否设置提取密码"`
+	MaxAccess   *int       `json:"max_access,omitempty" description:"最大访问次数，不限制时省略"`
+	MaxDownload *int       `json:"max_download,omitempty" description:"最大下载次数，不限制时省略"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty" description:"过期时间，永久有效时省略"`
+	CreatedAt   time.Time  `json:"created_at" description:"创建时间"`
+}
+
+// EventType 事件类型
+func (ShareCreatedV1) EventType() string { return TypeShareCreated }
+
+// EventVersion 载荷版本
+func (ShareCreatedV1) EventVersion() int { return 1 }
+
+// NewShareCreatedV1 从分享记录构建创建分享事件
+func NewShareCreatedV1(share *models.FileShare) *ShareCreatedV1 {
+	return &ShareCreatedV1{
+		ShareID:     share.ID,
+		ShareCode:   share.ShareCode,
+		FileID:      share.FileID,
+		SharerID:    share.SharerID,
+		Permission:  share.Permission,
+		HasPassword: share.HasPassword,
+		MaxAccess:   share.MaxAccess,
+		MaxDownload: share.MaxDownload,
+		ExpiresAt:   share.ExpiresAt,
+		CreatedAt:   share.CreatedAt.UTC(),
+	}
+}
+
+// UserSuspendedV1 用户被停用事件(v1)
+type UserSuspendedV1 struct {
+	UserID      uint      `json:"user_id" description:"用户ID"`
+	UserUUID    string    `json:"user_uuid" description:"用户唯一标识符"`
+	Reason      string    `json:"reason,omitempty" description:"停用原因"`
+	SuspendedBy *uint     `json:"suspended_by,omitempty" description:"执行停用的管理员ID，系统自动停用时省略"`
+	SuspendedAt time.Time `json:"suspended_at" description:"停用时间"`
+}
+
+// EventType 事件类型
+func (UserSuspendedV1) EventType() string { return TypeUserSuspended }
+
+// EventVersion 载荷版本
+func (UserSuspendedV1) EventVersion() int { return 1 }
+
+// NewUserSuspendedV1 构建用户停用事件，adminID为nil表示系统自动停用
+func NewUserSuspendedV1(user *models.User, reason string, adminID *uint, suspendedAt time.Time) *UserSuspendedV1 {
+	return &UserSuspendedV1{
+		UserID:      user.ID,
+		UserUUID:    user.UUID,
+		Reason:      reason,
+		SuspendedBy: adminID,
+		SuspendedAt: suspendedAt.UTC(),
+	}
+}
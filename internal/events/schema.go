@@ -0,0 +1,143 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// SchemaDialect 生成的JSON Schema版本
+const SchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchema JSON Schema文档，只包含事件载荷用到的关键字
+type JSONSchema struct {
+	Schema      string                 `json:"$schema,omitempty"`
+	ID          string                 `json:"$id,omitempty"`
+	Title       string                 `json:"title,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	Type        string                 `json:"type,omitempty"`
+	Format      string                 `json:"format,omitempty"`
+	Const       interface{}            `json:"const,omitempty"`
+	Enum        []string               `json:"enum,omitempty"`
+	Minimum     *int64                 `json:"minimum,omitempty"`
+	Properties  map[string]*JSONSchema `json:"properties,omitempty"`
+	Required    []string               `json:"required,omitempty"`
+	Items       *JSONSchema            `json:"items,omitempty"`
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// SchemaID 事件版本的Schema标识
+func SchemaID(eventType string, version int) string {
+	return fmt.Sprintf("urn:cloudpan:event:%s:v%d", eventType, version)
+}
+
+// Schema 生成事件版本的JSON Schema，描述包含该版本载荷的完整信封
+//
+// 载荷未声明禁止额外字段，消费方应忽略不认识的字段，同一版本新增可选字段不影响已有消费方
+func Schema(eventType string, version int) (*JSONSchema, error) {
+	definition, ok := Lookup(eventType, version)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s v%d", ErrUnknownEvent, eventType, version)
+	}
+	data, err := schemaOf(reflect.TypeOf(definition.New()))
+	if err != nil {
+		return nil, fmt.Errorf("%s v%d: %w", eventType, version, err)
+	}
+	data.Description = "事件载荷"
+
+	return &JSONSchema{
+		Schema:      SchemaDialect,
+		ID:          SchemaID(eventType, version),
+		Title:       fmt.Sprintf("%s v%d", eventType, version),
+		Description: definition.Description,
+		Type:        "object",
+		Properties: map[string]*JSONSchema{
+			"id":          {Type: "string", Description: "事件ID，消费方据此去重"},
+			"type":        {Type: "string", Const: eventType, Description: "事件类型"},
+			"version":     {Type: "integer", Const: version, Description: "载荷版本"},
+			"occurred_at": {Type: "string", Format: "date-time", Description: "事件发生时间(UTC)"},
+			"data":        data,
+		},
+		Required: []string{"id", "type", "version", "occurred_at", "data"},
+	}, nil
+}
+
+// MarshalSchema 生成事件版本的JSON Schema并格式化为缩进的JSON
+func MarshalSchema(eventType string, version int) ([]byte, error) {
+	schema, err := Schema(eventType, version)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+// schemaOf 按Go类型生成Schema，结构体字段按json标签命名
+func schemaOf(t reflect.Type) (*JSONSchema, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return &JSONSchema{Type: "string", Format: "date-time"}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		return structSchema(t)
+	case reflect.String:
+		return &JSONSchema{Type: "string"}, nil
+	case reflect.Bool:
+		return &JSONSchema{Type: "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &JSONSchema{Type: "integer"}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		minimum := int64(0)
+		return &JSONSchema{Type: "integer", Minimum: &minimum}, nil
+	case reflect.Float32, reflect.Float64:
+		return &JSONSchema{Type: "number"}, nil
+	case reflect.Slice, reflect.Array:
+		items, err := schemaOf(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &JSONSchema{Type: "array", Items: items}, nil
+	default:
+		return nil, fmt.Errorf("unsupported payload type %s", t)
+	}
+}
+
+// structSchema 生成结构体的Schema，指针和omitempty字段为可选字段
+func structSchema(t reflect.Type) (*JSONSchema, error) {
+	schema := &JSONSchema{Type: "object", Properties: map[string]*JSONSchema{}}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+
+		property, err := schemaOf(field.Type)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", field.Name, err)
+		}
+		property.Description = field.Tag.Get("description")
+		if enum := field.Tag.Get("enum"); enum != "" {
+			property.Enum = strings.Split(enum, ",")
+		}
+		schema.Properties[name] = property
+
+		if field.Type.Kind() != reflect.Ptr && !strings.Contains(options, "omitempty") {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+	return schema, nil
+}
@@ -0,0 +1,51 @@
+package events
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchema_AllDefinitions(t *testing.T) {
+	for _, definition := range Definitions() {
+		body, err := MarshalSchema(definition.Type, definition.Version)
+		require.NoError(t, err, definition.Type)
+
+		var document map[string]interface{}
+		require.NoError(t, json.Unmarshal(body, &document))
+		assert.Equal(t, SchemaDialect, document["$schema"])
+		assert.Equal(t, SchemaID(definition.Type, definition.Version), document["$id"])
+	}
+}
+
+func TestSchema_FileUploadedV1(t *testing.T) {
+	schema, err := Schema(TypeFileUploaded, 1)
+	require.NoError(t, err)
+	assert.Equal(t, TypeFileUploaded, schema.Properties["type"].Const)
+	assert.Equal(t, 1, schema.Properties["version"].Const)
+
+	data := schema.Properties["data"]
+	assert.Equal(t, "object", data.Type)
+	assert.Equal(t, []string{"file_id", "file_uuid", "owner_id", "name", "path", "size", "uploaded_at"}, data.Required)
+	assert.Equal(t, "date-time", data.Properties["uploaded_at"].Format)
+	assert.Equal(t, "integer", data.Properties["parent_id"].Type, "指针字段按元素类型生成")
+	require.NotNil(t, data.Properties["file_id"].Minimum)
+	assert.Equal(t, "文件ID", data.Properties["file_id"].Description)
+}
+
+func TestSchema_Enum(t *testing.T) {
+	schema, err := Schema(TypeShareCreated, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"view", "download", "edit"}, schema.Properties["data"].Properties["permission"].Enum)
+}
+
+func TestSchema_Unknown(t *testing.T) {
+	_, err := Schema("file.renamed", 1)
+	assert.ErrorIs(t, err, ErrUnknownEvent)
+
+	_, err = schemaOf(reflect.TypeOf(map[string]int{}))
+	assert.Error(t, err)
+}
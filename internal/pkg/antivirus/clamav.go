@@ -0,0 +1,162 @@
+// Package antivirus 提供上传文件的病毒扫描，通过clamd守护进程检测
+//
+// 数据以流的形式发送给clamd，不在本地落盘；是否检出由clamd的病毒库决定。
+package antivirus
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// 默认参数
+const (
+	DefaultSocket  = "/var/run/clamav/clamd.ctl"
+	DefaultTimeout = 60 * time.Second
+
+	chunkSize = 64 << 10 // INSTREAM每个数据块的大小
+)
+
+// ErrSizeLimitExceeded 文件超过clamd的StreamMaxLength限制
+var ErrSizeLimitExceeded = errors.New("clamav stream size limit exceeded")
+
+// Result 扫描结果
+type Result struct {
+	Infected  bool   // 是否检出病毒
+	Signature string // 检出的病毒特征名，未检出时为空
+}
+
+// Scanner 病毒扫描接口
+type Scanner interface {
+	Scan(ctx context.Context, src io.Reader) (*Result, error)
+}
+
+// ClamAV 通过clamd的INSTREAM命令扫描数据流
+//
+// Address 为unix socket路径，或 tcp://host:port 形式的TCP地址。
+// 每次扫描建立一个新连接，数据按块发送，不在本地落盘
+//
+// 使用示例：
+//
+//	scanner := &ClamAV{Address: "/var/run/clamav/clamd.ctl", Timeout: time.Minute}
+//	result, err := scanner.Scan(ctx, reader)
+type ClamAV struct {
+	Address string        // clamd地址，为空时使用 DefaultSocket
+	Timeout time.Duration // 单次扫描超时(含传输)，为0时使用 DefaultTimeout
+}
+
+// Scan 扫描数据流
+func (c *ClamAV) Scan(ctx context.Context, src io.Reader) (*Result, error) {
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	network, address := c.endpoint()
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, fmt.Errorf("连接clamd失败: %w", err)
+	}
+	defer conn.Close()
+
+	// 上下文取消时关闭连接，中断阻塞的读写
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if err := stream(conn, src); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && !(errors.Is(err, io.EOF) && reply != "") {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("读取扫描结果失败: %w", err)
+	}
+	return parseReply(strings.TrimRight(reply, "\x00\n"))
+}
+
+// endpoint 解析clamd地址
+func (c *ClamAV) endpoint() (string, string) {
+	address := c.Address
+	if address == "" {
+		address = DefaultSocket
+	}
+	if rest, ok := strings.CutPrefix(address, "tcp://"); ok {
+		return "tcp", rest
+	}
+	return "unix", strings.TrimPrefix(address, "unix://")
+}
+
+// stream 发送INSTREAM命令和数据块：每块前为4字节大端长度，长度为0的块表示结束
+func stream(conn net.Conn, src io.Reader) error {
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return fmt.Errorf("发送扫描命令失败: %w", err)
+	}
+
+	buf := make([]byte, 4+chunkSize)
+	for {
+		n, err := io.ReadFull(src, buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf[:4], uint32(n))
+			if _, werr := conn.Write(buf[:4+n]); werr != nil {
+				// clamd超过大小限制时先回复错误再断开连接，由调用方读取回复
+				if isConnReset(werr) {
+					return nil
+				}
+				return fmt.Errorf("发送扫描数据失败: %w", werr)
+			}
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("读取待扫描数据失败: %w", err)
+		}
+	}
+
+	if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil && !isConnReset(err) {
+		return fmt.Errorf("发送扫描结束标记失败: %w", err)
+	}
+	return nil
+}
+
+// parseReply 解析clamd回复，如 "stream: OK"、"stream: Eicar-Signature FOUND"
+func parseReply(reply string) (*Result, error) {
+	switch {
+	case strings.HasSuffix(reply, " OK"):
+		return &Result{}, nil
+	case strings.HasSuffix(reply, " FOUND"):
+		signature := strings.TrimSuffix(reply, " FOUND")
+		if i := strings.Index(signature, ": "); i >= 0 {
+			signature = signature[i+2:]
+		}
+		return &Result{Infected: true, Signature: signature}, nil
+	case strings.Contains(reply, "size limit exceeded"):
+		return nil, ErrSizeLimitExceeded
+	default:
+		return nil, fmt.Errorf("clamd返回错误: %s", reply)
+	}
+}
+
+// isConnReset 判断是否为对端关闭连接导致的写入失败
+func isConnReset(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "write"
+}
@@ -0,0 +1,128 @@
+package antivirus
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClamd 模拟clamd的INSTREAM命令：内容包含EICAR时报告病毒，超过limit字节时报告大小超限
+func fakeClamd(t *testing.T, network string, limit int) string {
+	t.Helper()
+	address := "127.0.0.1:0"
+	if network == "unix" {
+		address = filepath.Join(t.TempDir(), "clamd.sock")
+	}
+	listener, err := net.Listen(network, address)
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveClamd(conn, limit)
+		}
+	}()
+	if network == "unix" {
+		return address
+	}
+	return "tcp://" + listener.Addr().String()
+}
+
+func serveClamd(conn net.Conn, limit int) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	command, err := reader.ReadString(0)
+	if err != nil || command != "zINSTREAM\x00" {
+		_, _ = conn.Write([]byte("UNKNOWN COMMAND\x00"))
+		return
+	}
+
+	var data bytes.Buffer
+	header := make([]byte, 4)
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			return
+		}
+		size := binary.BigEndian.Uint32(header)
+		if size == 0 {
+			break
+		}
+		if _, err := io.CopyN(&data, reader, int64(size)); err != nil {
+			return
+		}
+		if data.Len() > limit {
+			_, _ = conn.Write([]byte("INSTREAM size limit exceeded. ERROR\x00"))
+			return
+		}
+	}
+
+	if bytes.Contains(data.Bytes(), []byte("EICAR")) {
+		_, _ = conn.Write([]byte("stream: Eicar-Test-Signature FOUND\x00"))
+		return
+	}
+	_, _ = conn.Write([]byte("stream: OK\x00"))
+}
+
+func TestClamAVScan(t *testing.T) {
+	ctx := context.Background()
+
+	for _, network := range []string{"unix", "tcp"} {
+		t.Run(network, func(t *testing.T) {
+			scanner := &ClamAV{Address: fakeClamd(t, network, 1<<20), Timeout: 5 * time.Second}
+
+			result, err := scanner.Scan(ctx, strings.NewReader("hello world"))
+			require.NoError(t, err)
+			assert.False(t, result.Infected)
+
+			// 跨越多个数据块的内容
+			payload := strings.Repeat("a", chunkSize+10) + "EICAR"
+			result, err = scanner.Scan(ctx, strings.NewReader(payload))
+			require.NoError(t, err)
+			assert.True(t, result.Infected)
+			assert.Equal(t, "Eicar-Test-Signature", result.Signature)
+
+			result, err = scanner.Scan(ctx, strings.NewReader(""))
+			require.NoError(t, err)
+			assert.False(t, result.Infected)
+		})
+	}
+
+	t.Run("size limit", func(t *testing.T) {
+		scanner := &ClamAV{Address: fakeClamd(t, "unix", 100)}
+		_, err := scanner.Scan(ctx, strings.NewReader(strings.Repeat("a", 4*chunkSize)))
+		assert.ErrorIs(t, err, ErrSizeLimitExceeded)
+	})
+
+	t.Run("unreachable", func(t *testing.T) {
+		scanner := &ClamAV{Address: filepath.Join(t.TempDir(), "missing.sock")}
+		_, err := scanner.Scan(ctx, strings.NewReader("x"))
+		assert.ErrorContains(t, err, "连接clamd失败")
+	})
+}
+
+func TestParseReply(t *testing.T) {
+	result, err := parseReply("stream: OK")
+	require.NoError(t, err)
+	assert.False(t, result.Infected)
+
+	result, err = parseReply("stream: Win.Test.EICAR_HDB-1 FOUND")
+	require.NoError(t, err)
+	assert.Equal(t, "Win.Test.EICAR_HDB-1", result.Signature)
+
+	_, err = parseReply("INSTREAM: Can't allocate memory ERROR")
+	assert.ErrorContains(t, err, "clamd返回错误")
+}
@@ -0,0 +1,596 @@
+// Package backup 数据库元数据备份与恢复
+//
+// 定期将关键表(用户、文件、分享)逐行导出为JSON，经gzip压缩和AES-256-GCM分块加密后写入存储后端，
+// 通过备份目录记录全部备份并按保留数量清理。恢复时先校验备份格式和表结构与当前数据库兼容，
+// 再在一个事务中清空目标表并写入备份数据，任一步失败则整体回滚。
+//
+// 使用示例：
+//
+//	service, err := backup.NewService(db, store, backup.OptionsFromConfig(cfg.Backup), logger)
+//	entry, err := service.Run(ctx)
+//	report, err := service.Restore(ctx, backup.LatestBackup, false) // 只校验，不写入
+package backup
+
+import (
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/buildinfo"
+	"cloudpan/internal/pkg/config"
+	basemodels "cloudpan/internal/pkg/database/models"
+	"cloudpan/internal/pkg/storage"
+)
+
+// FormatVersion 当前备份格式版本，格式不兼容变更时递增
+const FormatVersion = 1
+
+// LatestBackup 恢复时表示备份目录中最新的备份
+const LatestBackup = "latest"
+
+// 配置缺失时使用的内置默认值
+const (
+	defaultInterval  = 24 * time.Hour
+	defaultPrefix    = "backups/metadata"
+	defaultRetention = 7
+
+	restoreBatchSize = 500
+	backupExtension  = ".cpbak"
+)
+
+// DefaultTables 默认备份的表，按恢复时的写入顺序排列
+var DefaultTables = []string{"users", "files", "file_shares"}
+
+// Options 备份选项
+type Options struct {
+	Interval  time.Duration // 备份间隔
+	Tables    []string      // 备份的表
+	Prefix    string        // 存储路径前缀
+	Retention int           // 保留最近的备份数
+	ActiveKey string        // 当前加密密钥版本
+	Keys      []string      // 版本:Base64密钥 格式的密钥列表
+}
+
+// OptionsFromConfig 从备份配置生成备份选项
+func OptionsFromConfig(cfg config.BackupConfig) Options {
+	return Options{
+		Interval:  cfg.Interval,
+		Tables:    cfg.Tables,
+		Prefix:    cfg.Prefix,
+		Retention: cfg.Retention,
+		ActiveKey: cfg.ActiveKey,
+		Keys:      cfg.Keys,
+	}
+}
+
+// Column 备份中的列
+type Column struct {
+	Name string `json:"name"` // 列名
+	Type string `json:"type"` // 数据库类型名，用于恢复时还原时间类型
+}
+
+// TableSchema 备份中的表结构
+type TableSchema struct {
+	Name    string   `json:"name"`    // 表名
+	Columns []Column `json:"columns"` // 导出的列，按行数据中的顺序排列
+}
+
+// Manifest 备份清单，写在备份数据的开头，恢复前据此校验兼容性
+type Manifest struct {
+	FormatVersion int           `json:"format_version"` // 备份格式版本
+	CreatedAt     time.Time     `json:"created_at"`     // 创建时间
+	AppVersion    string        `json:"app_version"`    // 创建备份的应用版本
+	Dialect       string        `json:"dialect"`        // 数据库类型(mysql/sqlite)
+	Tables        []TableSchema `json:"tables"`         // 备份的表
+}
+
+// record 备份数据中的一条记录，第一条为清单，其余为按表顺序排列的行
+type record struct {
+	Manifest *Manifest `json:"manifest,omitempty"`
+	Table    string    `json:"table,omitempty"`
+	Values   []any     `json:"values,omitempty"`
+}
+
+// RestoreReport 恢复结果
+type RestoreReport struct {
+	Backup   string           `json:"backup"`   // 备份路径
+	Manifest *Manifest        `json:"manifest"` // 备份清单
+	Rows     map[string]int64 `json:"rows"`     // 各表读取(或写入)的行数
+	Warnings []string         `json:"warnings"` // 兼容但需要注意的差异
+	Applied  bool             `json:"applied"`  // 是否已写入数据库
+}
+
+// Service 元数据备份服务
+type Service struct {
+	db      *gorm.DB
+	store   storage.Storage
+	keyring *Keyring
+	options Options
+	logger  *zap.Logger
+	now     func() time.Time
+}
+
+// NewService 创建元数据备份服务，未配置加密密钥或密钥无效时返回错误
+func NewService(db *gorm.DB, store storage.Storage, options Options, logger *zap.Logger) (*Service, error) {
+	if db == nil || store == nil {
+		return nil, fmt.Errorf("备份需要数据库和存储后端")
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	if options.Interval <= 0 {
+		options.Interval = defaultInterval
+	}
+	if len(options.Tables) == 0 {
+		options.Tables = DefaultTables
+	}
+	if options.Prefix == "" {
+		options.Prefix = defaultPrefix
+	}
+	if options.Retention <= 0 {
+		options.Retention = defaultRetention
+	}
+
+	keys, err := basemodels.ParseFieldKeys(options.Keys)
+	if err != nil {
+		return nil, fmt.Errorf("解析备份加密密钥失败: %w", err)
+	}
+	keyring, err := NewKeyring(options.ActiveKey, keys)
+	if err != nil {
+		return nil, fmt.Errorf("初始化备份加密密钥失败: %w", err)
+	}
+
+	return &Service{
+		db:      db,
+		store:   store,
+		keyring: keyring,
+		options: options,
+		logger:  logger,
+		now:     time.Now,
+	}, nil
+}
+
+// Interval 备份间隔
+func (s *Service) Interval() time.Duration {
+	return s.options.Interval
+}
+
+// Run 执行一次备份并写入备份目录，超过保留数量的旧备份随之删除
+//
+// MySQL下在可重复读的只读事务中导出，各表数据来自同一快照
+func (s *Service) Run(ctx context.Context) (*Entry, error) {
+	createdAt := s.now().UTC()
+	name := "metadata-" + createdAt.Format("20060102T150405Z")
+	objectPath := path.Join(s.options.Prefix, name+backupExtension)
+
+	writer, err := s.store.Create(ctx, objectPath)
+	if err != nil {
+		return nil, fmt.Errorf("创建备份文件失败: %w", err)
+	}
+	counter := &countingWriter{w: writer}
+	rows, err := s.dump(ctx, counter, createdAt)
+	if err != nil {
+		writer.Abort() // #nosec G104 -- 已返回导出错误
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("写入备份文件失败: %w", err)
+	}
+
+	entry := Entry{
+		Name:          name,
+		Path:          objectPath,
+		CreatedAt:     createdAt,
+		Size:          counter.n,
+		Rows:          rows,
+		KeyVersion:    s.keyring.ActiveVersion(),
+		FormatVersion: FormatVersion,
+		AppVersion:    buildinfo.Get().Version,
+	}
+	if err := s.record(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Metadata backup completed",
+		zap.String("path", objectPath),
+		zap.Int64("size", entry.Size),
+		zap.Any("rows", rows))
+	return &entry, nil
+}
+
+// List 列出备份目录中的全部备份，按创建时间排序
+func (s *Service) List(ctx context.Context) ([]Entry, error) {
+	c, err := loadCatalog(ctx, s.store, s.options.Prefix)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(c.Backups, func(a, b Entry) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return c.Backups, nil
+}
+
+// Restore 校验并恢复备份，name为备份名称、存储路径或 LatestBackup
+//
+// apply为false时只校验兼容性并完整读取一遍备份(验证加密分块)，不修改数据库；
+// apply为true时在一个事务中清空备份中的表并写入备份数据
+func (s *Service) Restore(ctx context.Context, name string, apply bool) (*RestoreReport, error) {
+	objectPath, err := s.resolve(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := s.store.Open(ctx, objectPath)
+	if err != nil {
+		return nil, fmt.Errorf("打开备份文件失败: %w", err)
+	}
+	defer reader.Close()
+
+	decrypted, err := s.keyring.NewDecryptReader(reader)
+	if err != nil {
+		return nil, err
+	}
+	gz, err := gzip.NewReader(decrypted)
+	if err != nil {
+		return nil, fmt.Errorf("解压备份文件失败: %w", err)
+	}
+	decoder := json.NewDecoder(gz)
+	decoder.UseNumber()
+
+	var first record
+	if err := decoder.Decode(&first); err != nil || first.Manifest == nil {
+		return nil, fmt.Errorf("备份文件缺少清单")
+	}
+	report := &RestoreReport{Backup: objectPath, Manifest: first.Manifest, Rows: make(map[string]int64)}
+	report.Warnings, err = s.validate(first.Manifest)
+	if err != nil {
+		return report, err
+	}
+
+	if !apply {
+		err = readRecords(decoder, first.Manifest, func(table string, _ []any) error {
+			report.Rows[table]++
+			return nil
+		})
+		return report, err
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return s.apply(tx, decoder, first.Manifest, report)
+	})
+	if err != nil {
+		return report, err
+	}
+	report.Applied = true
+
+	s.logger.Warn("Metadata backup restored",
+		zap.String("path", objectPath),
+		zap.Any("rows", report.Rows))
+	return report, nil
+}
+
+// dump 导出全部表，依次经过gzip压缩和分块加密写入w，返回各表行数
+func (s *Service) dump(ctx context.Context, w io.Writer, createdAt time.Time) (map[string]int64, error) {
+	encrypted, err := s.keyring.NewEncryptWriter(w)
+	if err != nil {
+		return nil, err
+	}
+	gz := gzip.NewWriter(encrypted)
+	encoder := json.NewEncoder(gz)
+
+	rows := make(map[string]int64, len(s.options.Tables))
+	var txOptions []*sql.TxOptions
+	if s.db.Dialector.Name() == "mysql" {
+		txOptions = append(txOptions, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	}
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		manifest := &Manifest{
+			FormatVersion: FormatVersion,
+			CreatedAt:     createdAt,
+			AppVersion:    buildinfo.Get().Version,
+			Dialect:       tx.Dialector.Name(),
+		}
+		for _, table := range s.options.Tables {
+			schema, err := tableSchema(tx, table)
+			if err != nil {
+				return err
+			}
+			manifest.Tables = append(manifest.Tables, *schema)
+		}
+		if err := encoder.Encode(record{Manifest: manifest}); err != nil {
+			return err
+		}
+
+		for _, schema := range manifest.Tables {
+			count, err := dumpTable(tx, encoder, schema)
+			if err != nil {
+				return fmt.Errorf("导出 %s 失败: %w", schema.Name, err)
+			}
+			rows[schema.Name] = count
+		}
+		return nil
+	}, txOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	if err := encrypted.Close(); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// record 将备份加入目录并删除超过保留数量的旧备份，删除失败只记录日志
+func (s *Service) record(ctx context.Context, entry Entry) error {
+	c, err := loadCatalog(ctx, s.store, s.options.Prefix)
+	if err != nil {
+		return err
+	}
+	c.Backups = append(c.Backups, entry)
+	expired := c.expire(s.options.Retention)
+	if err := c.save(ctx, s.store, s.options.Prefix); err != nil {
+		return err
+	}
+
+	for _, old := range expired {
+		if err := s.store.Delete(ctx, old.Path); err != nil {
+			s.logger.Warn("Failed to delete expired backup", zap.String("path", old.Path), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// resolve 将备份名称解析为存储路径，目录中没有记录时按存储路径处理
+func (s *Service) resolve(ctx context.Context, name string) (string, error) {
+	c, err := loadCatalog(ctx, s.store, s.options.Prefix)
+	if err != nil {
+		return "", err
+	}
+	if entry, ok := c.find(name); ok {
+		return entry.Path, nil
+	}
+	if name == LatestBackup || name == "" {
+		return "", fmt.Errorf("备份目录中没有备份")
+	}
+	return name, nil
+}
+
+// validate 校验备份与当前数据库兼容，返回不影响恢复的差异
+//
+// 备份格式版本更高、表不存在或备份中的列在当前表中不存在时不兼容
+func (s *Service) validate(manifest *Manifest) ([]string, error) {
+	if manifest.FormatVersion > FormatVersion {
+		return nil, fmt.Errorf("备份格式版本 %d 高于当前支持的版本 %d，请使用更新的版本恢复", manifest.FormatVersion, FormatVersion)
+	}
+
+	var warnings []string
+	if dialect := s.db.Dialector.Name(); manifest.Dialect != dialect {
+		warnings = append(warnings, fmt.Sprintf("备份来自 %s 数据库，当前为 %s", manifest.Dialect, dialect))
+	}
+	if version := buildinfo.Get().Version; manifest.AppVersion != version {
+		warnings = append(warnings, fmt.Sprintf("备份由版本 %s 创建，当前版本为 %s", manifest.AppVersion, version))
+	}
+
+	var problems []string
+	for _, table := range manifest.Tables {
+		if !s.db.Migrator().HasTable(table.Name) {
+			problems = append(problems, fmt.Sprintf("表 %s 不存在", table.Name))
+			continue
+		}
+		current, err := tableSchema(s.db, table.Name)
+		if err != nil {
+			return nil, err
+		}
+		existing := make(map[string]bool, len(current.Columns))
+		for _, column := range current.Columns {
+			existing[column.Name] = true
+		}
+
+		var missing []string
+		backedUp := make(map[string]bool, len(table.Columns))
+		for _, column := range table.Columns {
+			backedUp[column.Name] = true
+			if !existing[column.Name] {
+				missing = append(missing, column.Name)
+			}
+		}
+		if len(missing) > 0 {
+			problems = append(problems, fmt.Sprintf("表 %s 缺少列 %s", table.Name, strings.Join(missing, ", ")))
+		}
+
+		var added []string
+		for _, column := range current.Columns {
+			if !backedUp[column.Name] {
+				added = append(added, column.Name)
+			}
+		}
+		if len(added) > 0 {
+			warnings = append(warnings, fmt.Sprintf("表 %s 的新增列 %s 将使用默认值", table.Name, strings.Join(added, ", ")))
+		}
+	}
+	if len(problems) > 0 {
+		return warnings, fmt.Errorf("备份与当前数据库不兼容: %s", strings.Join(problems, "; "))
+	}
+	return warnings, nil
+}
+
+// apply 清空备份中的表并按批写入备份数据
+//
+// MySQL下临时关闭外键检查，表之间的写入顺序不受外键约束影响
+func (s *Service) apply(tx *gorm.DB, decoder *json.Decoder, manifest *Manifest, report *RestoreReport) error {
+	if tx.Dialector.Name() == "mysql" {
+		if err := tx.Exec("SET FOREIGN_KEY_CHECKS = 0").Error; err != nil {
+			return err
+		}
+		defer tx.Exec("SET FOREIGN_KEY_CHECKS = 1")
+	}
+
+	schemas := make(map[string]TableSchema, len(manifest.Tables))
+	for i := len(manifest.Tables) - 1; i >= 0; i-- {
+		table := manifest.Tables[i]
+		schemas[table.Name] = table
+		if err := tx.Exec("DELETE FROM " + tx.Statement.Quote(table.Name)).Error; err != nil {
+			return fmt.Errorf("清空 %s 失败: %w", table.Name, err)
+		}
+	}
+
+	var batch []map[string]any
+	var batchTable string
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := tx.Table(batchTable).Create(&batch).Error; err != nil {
+			return fmt.Errorf("写入 %s 失败: %w", batchTable, err)
+		}
+		report.Rows[batchTable] += int64(len(batch))
+		batch = batch[:0]
+		return nil
+	}
+
+	err := readRecords(decoder, manifest, func(table string, values []any) error {
+		if table != batchTable || len(batch) >= restoreBatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+			batchTable = table
+		}
+		row := make(map[string]any, len(values))
+		for i, column := range schemas[table].Columns {
+			row[column.Name] = restoreValue(values[i], column.Type)
+		}
+		batch = append(batch, row)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return flush()
+}
+
+// readRecords 读取清单之后的全部行，行所属的表必须在清单中且列数一致
+func readRecords(decoder *json.Decoder, manifest *Manifest, fn func(table string, values []any) error) error {
+	columns := make(map[string]int, len(manifest.Tables))
+	for _, table := range manifest.Tables {
+		columns[table.Name] = len(table.Columns)
+	}
+
+	for {
+		var r record
+		err := decoder.Decode(&r)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("读取备份数据失败: %w", err)
+		}
+		count, ok := columns[r.Table]
+		if !ok || len(r.Values) != count {
+			return fmt.Errorf("备份数据与清单不一致: 表 %q", r.Table)
+		}
+		if err := fn(r.Table, r.Values); err != nil {
+			return err
+		}
+	}
+}
+
+// tableSchema 读取表的列名和类型
+func tableSchema(db *gorm.DB, table string) (*TableSchema, error) {
+	columnTypes, err := db.Migrator().ColumnTypes(table)
+	if err != nil {
+		return nil, fmt.Errorf("读取 %s 表结构失败: %w", table, err)
+	}
+	if len(columnTypes) == 0 {
+		return nil, fmt.Errorf("表 %s 不存在或没有列", table)
+	}
+
+	schema := &TableSchema{Name: table}
+	for _, columnType := range columnTypes {
+		schema.Columns = append(schema.Columns, Column{Name: columnType.Name(), Type: columnType.DatabaseTypeName()})
+	}
+	return schema, nil
+}
+
+// dumpTable 逐行导出表的全部记录(包括软删除的记录)，返回行数
+func dumpTable(tx *gorm.DB, encoder *json.Encoder, schema TableSchema) (int64, error) {
+	names := make([]string, len(schema.Columns))
+	for i, column := range schema.Columns {
+		names[i] = column.Name
+	}
+
+	rows, err := tx.Table(schema.Name).Select(names).Rows()
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var count int64
+	values := make([]any, len(names))
+	pointers := make([]any, len(names))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(pointers...); err != nil {
+			return count, err
+		}
+		out := make([]any, len(values))
+		for i, value := range values {
+			if raw, ok := value.([]byte); ok {
+				value = string(raw)
+			}
+			out[i] = value
+		}
+		if err := encoder.Encode(record{Table: schema.Name, Values: out}); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, rows.Err()
+}
+
+// restoreValue 将JSON解码的值还原为写入数据库的类型
+func restoreValue(value any, columnType string) any {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case string:
+		upper := strings.ToUpper(columnType)
+		if strings.Contains(upper, "DATE") || strings.Contains(upper, "TIME") {
+			if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+				return t
+			}
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// countingWriter 统计写入的字节数
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,215 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	_ "modernc.org/sqlite" // 使用纯Go的SQLite驱动
+
+	"cloudpan/internal/pkg/storage"
+)
+
+// backupTestUser 测试用的用户表
+type backupTestUser struct {
+	ID        uint `gorm:"primaryKey"`
+	Username  string
+	Active    bool
+	CreatedAt time.Time
+	DeletedAt gorm.DeletedAt
+}
+
+func (backupTestUser) TableName() string { return "users" }
+
+// backupTestFile 测试用的文件表
+type backupTestFile struct {
+	ID     uint `gorm:"primaryKey"`
+	UserID uint
+	Name   string
+	Size   int64
+}
+
+func (backupTestFile) TableName() string { return "files" }
+
+func testKey(b byte) string {
+	return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, 32))
+}
+
+func setupBackupTest(t *testing.T) (*gorm.DB, storage.Storage, *Service) {
+	t.Helper()
+	sqlDB, err := sql.Open("sqlite", ":memory:")
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	db, err := gorm.Open(sqlite.Dialector{Conn: sqlDB}, &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&backupTestUser{}, &backupTestFile{}))
+
+	store, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	service, err := NewService(db, store, Options{
+		Tables:    []string{"users", "files"},
+		Retention: 2,
+		ActiveKey: "v1",
+		Keys:      []string{"v1:" + testKey(1)},
+	}, nil)
+	require.NoError(t, err)
+	return db, store, service
+}
+
+func TestKeyring_RoundTrip(t *testing.T) {
+	keyring, err := NewKeyring("v1", map[string]string{"v1": testKey(1)})
+	require.NoError(t, err)
+
+	plain := bytes.Repeat([]byte("cloudpan backup "), 10000) // 跨越多个分块
+	var buf bytes.Buffer
+	w, err := keyring.NewEncryptWriter(&buf)
+	require.NoError(t, err)
+	_, err = w.Write(plain)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	assert.False(t, bytes.Contains(buf.Bytes(), []byte("cloudpan backup")))
+
+	r, err := keyring.NewDecryptReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	got, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, plain, got)
+
+	// 截断到最后一个分块之前
+	sealed := buf.Bytes()
+	r, err = keyring.NewDecryptReader(bytes.NewReader(sealed[:len(sealed)-streamChunkSize/2]))
+	require.NoError(t, err)
+	_, err = io.ReadAll(r)
+	assert.Error(t, err)
+
+	// 篡改密文
+	tampered := append([]byte(nil), sealed...)
+	tampered[len(tampered)-1] ^= 0xff
+	r, err = keyring.NewDecryptReader(bytes.NewReader(tampered))
+	require.NoError(t, err)
+	_, err = io.ReadAll(r)
+	assert.Error(t, err)
+
+	// 未配置备份使用的密钥版本
+	other, err := NewKeyring("v2", map[string]string{"v2": testKey(2)})
+	require.NoError(t, err)
+	_, err = other.NewDecryptReader(bytes.NewReader(sealed))
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+func TestNewService_RequiresKey(t *testing.T) {
+	_, store, _ := setupBackupTest(t)
+	_, err := NewService(&gorm.DB{}, store, Options{}, nil)
+	assert.Error(t, err)
+}
+
+func TestService_BackupAndRestore(t *testing.T) {
+	db, _, service := setupBackupTest(t)
+	ctx := context.Background()
+
+	createdAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.NoError(t, db.Create(&backupTestUser{ID: 1, Username: "alice", Active: true, CreatedAt: createdAt}).Error)
+	deleted := &backupTestUser{ID: 2, Username: "bob", CreatedAt: createdAt}
+	require.NoError(t, db.Create(deleted).Error)
+	require.NoError(t, db.Delete(deleted).Error)
+	require.NoError(t, db.Create(&backupTestFile{ID: 10, UserID: 1, Name: "a.txt", Size: 42}).Error)
+
+	entry, err := service.Run(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"users": 2, "files": 1}, entry.Rows)
+	assert.Equal(t, "v1", entry.KeyVersion)
+
+	// 修改数据后恢复
+	require.NoError(t, db.Exec("DELETE FROM files").Error)
+	require.NoError(t, db.Model(&backupTestUser{}).Where("id = ?", 1).Update("username", "mallory").Error)
+	require.NoError(t, db.Create(&backupTestUser{ID: 3, Username: "carol"}).Error)
+
+	// 未确认时只校验，不修改数据
+	report, err := service.Restore(ctx, LatestBackup, false)
+	require.NoError(t, err)
+	assert.False(t, report.Applied)
+	assert.Equal(t, map[string]int64{"users": 2, "files": 1}, report.Rows)
+	var count int64
+	db.Model(&backupTestFile{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+
+	report, err = service.Restore(ctx, entry.Name, true)
+	require.NoError(t, err)
+	assert.True(t, report.Applied)
+
+	var users []backupTestUser
+	require.NoError(t, db.Unscoped().Order("id").Find(&users).Error)
+	require.Len(t, users, 2)
+	assert.Equal(t, "alice", users[0].Username)
+	assert.True(t, users[0].Active)
+	assert.True(t, users[0].CreatedAt.Equal(createdAt))
+	assert.True(t, users[1].DeletedAt.Valid)
+
+	var file backupTestFile
+	require.NoError(t, db.First(&file, 10).Error)
+	assert.Equal(t, int64(42), file.Size)
+}
+
+func TestService_RestoreIncompatible(t *testing.T) {
+	db, _, service := setupBackupTest(t)
+	ctx := context.Background()
+
+	require.NoError(t, db.Create(&backupTestFile{ID: 1, Name: "a.txt"}).Error)
+	_, err := service.Run(ctx)
+	require.NoError(t, err)
+
+	// 当前表缺少备份中的列时拒绝恢复，数据保持不变
+	require.NoError(t, db.Migrator().DropColumn(&backupTestFile{}, "size"))
+	require.NoError(t, db.Exec("INSERT INTO files (id, name) VALUES (2, ?)", "b.txt").Error)
+	_, err = service.Restore(ctx, LatestBackup, true)
+	require.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "size"))
+
+	var count int64
+	db.Table("files").Count(&count)
+	assert.Equal(t, int64(2), count)
+
+	// 其他密钥无法读取备份
+	other, err := NewService(db, service.store, Options{
+		Tables:    []string{"users", "files"},
+		ActiveKey: "v2",
+		Keys:      []string{"v2:" + testKey(2)},
+	}, nil)
+	require.NoError(t, err)
+	_, err = other.Restore(ctx, LatestBackup, false)
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+func TestService_Retention(t *testing.T) {
+	_, store, service := setupBackupTest(t)
+	ctx := context.Background()
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var first *Entry
+	for i := 0; i < 3; i++ {
+		service.now = func() time.Time { return now.Add(time.Duration(i) * time.Hour) }
+		entry, err := service.Run(ctx)
+		require.NoError(t, err)
+		if first == nil {
+			first = entry
+		}
+	}
+
+	entries, err := service.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.NotEqual(t, first.Name, entries[0].Name)
+
+	exists, err := store.Exists(ctx, first.Path)
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
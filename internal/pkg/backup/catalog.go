@@ -0,0 +1,106 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"time"
+
+	"cloudpan/internal/pkg/storage"
+)
+
+// catalogName 备份目录在前缀下的对象名
+const catalogName = "index.json"
+
+// Entry 备份目录中的一个备份
+type Entry struct {
+	Name          string           `json:"name"`           // 备份名称
+	Path          string           `json:"path"`           // 存储路径
+	CreatedAt     time.Time        `json:"created_at"`     // 创建时间
+	Size          int64            `json:"size"`           // 加密后的大小(字节)
+	Rows          map[string]int64 `json:"rows"`           // 各表行数
+	KeyVersion    string           `json:"key_version"`    // 加密密钥版本
+	FormatVersion int              `json:"format_version"` // 备份格式版本
+	AppVersion    string           `json:"app_version"`    // 创建备份的应用版本
+}
+
+// catalog 备份目录，存储后端不支持列举对象，通过目录记录全部备份以便按保留数量清理
+type catalog struct {
+	Backups []Entry `json:"backups"`
+}
+
+// loadCatalog 读取备份目录，不存在时返回空目录
+func loadCatalog(ctx context.Context, store storage.Storage, prefix string) (*catalog, error) {
+	reader, err := store.Open(ctx, path.Join(prefix, catalogName))
+	if storage.IsNotFound(err) {
+		return &catalog{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取备份目录失败: %w", err)
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("读取备份目录失败: %w", err)
+	}
+	var c catalog
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("解析备份目录失败: %w", err)
+	}
+	return &c, nil
+}
+
+// save 按创建时间排序后写回备份目录
+func (c *catalog) save(ctx context.Context, store storage.Storage, prefix string) error {
+	sort.Slice(c.Backups, func(i, j int) bool {
+		return c.Backups[i].CreatedAt.Before(c.Backups[j].CreatedAt)
+	})
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := store.Put(ctx, path.Join(prefix, catalogName), bytes.NewReader(data), int64(len(data))); err != nil {
+		return fmt.Errorf("写入备份目录失败: %w", err)
+	}
+	return nil
+}
+
+// expire 移出超过保留数量的最旧备份并返回它们
+func (c *catalog) expire(retention int) []Entry {
+	if retention <= 0 || len(c.Backups) <= retention {
+		return nil
+	}
+	sort.Slice(c.Backups, func(i, j int) bool {
+		return c.Backups[i].CreatedAt.Before(c.Backups[j].CreatedAt)
+	})
+	expired := append([]Entry(nil), c.Backups[:len(c.Backups)-retention]...)
+	c.Backups = c.Backups[len(c.Backups)-retention:]
+	return expired
+}
+
+// find 按名称查找备份，name为latest时返回最新的备份
+func (c *catalog) find(name string) (Entry, bool) {
+	if len(c.Backups) == 0 {
+		return Entry{}, false
+	}
+	if name == LatestBackup {
+		latest := c.Backups[0]
+		for _, entry := range c.Backups[1:] {
+			if entry.CreatedAt.After(latest.CreatedAt) {
+				latest = entry
+			}
+		}
+		return latest, true
+	}
+	for _, entry := range c.Backups {
+		if entry.Name == name || entry.Path == name {
+			return entry, true
+		}
+	}
+	return Entry{}, false
+}
@@ -0,0 +1,263 @@
+package backup
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// 加密流格式
+//
+//	CPBAK1\n
+//	{"key_version":"v1","salt":"..."}\n    明文头部，恢复时据此选择密钥
+//	[4字节密文长度][密文分块]...           AES-256-GCM分块加密
+//
+// 每个备份使用随机盐从主密钥派生独立的数据密钥，分块序号作为nonce，
+// 最后一个分块的附加数据带结束标记，截断或重排分块都会导致解密失败
+const (
+	streamMagic     = "CPBAK1\n"
+	streamChunkSize = 64 * 1024
+	maxSealedChunk  = streamChunkSize + 16
+)
+
+// ErrKeyNotFound 备份使用的密钥版本未配置
+var ErrKeyNotFound = errors.New("backup encryption key version not found")
+
+// streamHeader 加密流明文头部
+type streamHeader struct {
+	KeyVersion string `json:"key_version"` // 主密钥版本
+	Salt       string `json:"salt"`        // 派生数据密钥的随机盐(Base64)
+}
+
+// Keyring 备份加密密钥环，新备份使用当前版本加密，旧版本仅用于恢复
+type Keyring struct {
+	activeVersion string
+	keys          map[string][]byte
+}
+
+// NewKeyring 创建备份加密密钥环
+//
+// keys 为 版本 -> Base64编码的32字节主密钥；activeVersion 必须存在于keys中
+func NewKeyring(activeVersion string, keys map[string]string) (*Keyring, error) {
+	if activeVersion == "" {
+		return nil, fmt.Errorf("未指定备份加密密钥版本")
+	}
+
+	keyring := &Keyring{activeVersion: activeVersion, keys: make(map[string][]byte, len(keys))}
+	for version, encoded := range keys {
+		if version == "" || strings.ContainsAny(version, ":\n") {
+			return nil, fmt.Errorf("无效的密钥版本: %q", version)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("密钥 %s 不是有效的Base64: %w", version, err)
+		}
+		if len(key) != 32 {
+			return nil, fmt.Errorf("密钥 %s 长度必须为32字节，实际为 %d 字节", version, len(key))
+		}
+		keyring.keys[version] = key
+	}
+
+	if _, ok := keyring.keys[activeVersion]; !ok {
+		return nil, fmt.Errorf("当前密钥版本 %s: %w", activeVersion, ErrKeyNotFound)
+	}
+	return keyring, nil
+}
+
+// ActiveVersion 当前加密使用的密钥版本
+func (k *Keyring) ActiveVersion() string {
+	return k.activeVersion
+}
+
+// aead 按版本和盐派生数据密钥并创建AES-GCM
+func (k *Keyring) aead(version string, salt []byte) (cipher.AEAD, error) {
+	master, ok := k.keys[version]
+	if !ok {
+		return nil, fmt.Errorf("密钥版本 %s: %w", version, ErrKeyNotFound)
+	}
+	mac := hmac.New(sha256.New, master)
+	mac.Write([]byte("cloudpan-backup:"))
+	mac.Write(salt)
+
+	block, err := aes.NewCipher(mac.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encryptWriter 分块加密写入器
+type encryptWriter struct {
+	dst     io.Writer
+	aead    cipher.AEAD
+	buf     []byte
+	counter uint64
+	closed  bool
+}
+
+// NewEncryptWriter 创建使用当前版本密钥的加密写入器，Close时写入最后一个分块，不关闭dst
+func (k *Keyring) NewEncryptWriter(dst io.Writer) (io.WriteCloser, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("生成备份加密盐失败: %w", err)
+	}
+	aead, err := k.aead(k.activeVersion, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	header, err := json.Marshal(streamHeader{KeyVersion: k.activeVersion, Salt: base64.StdEncoding.EncodeToString(salt)})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(dst, streamMagic+string(header)+"\n"); err != nil {
+		return nil, fmt.Errorf("写入备份头部失败: %w", err)
+	}
+	return &encryptWriter{dst: dst, aead: aead, buf: make([]byte, 0, streamChunkSize*2)}, nil
+}
+
+// Write 缓冲数据，超过一个分块时加密写出，最后一个分块留到Close时写出
+func (w *encryptWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New("加密写入器已关闭")
+	}
+	w.buf = append(w.buf, p...)
+	for len(w.buf) > streamChunkSize {
+		if err := w.seal(w.buf[:streamChunkSize], false); err != nil {
+			return 0, err
+		}
+		w.buf = append(w.buf[:0], w.buf[streamChunkSize:]...)
+	}
+	return len(p), nil
+}
+
+// Close 加密写出最后一个分块
+func (w *encryptWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	return w.seal(w.buf, true)
+}
+
+// seal 加密一个分块并写出长度和密文
+func (w *encryptWriter) seal(chunk []byte, final bool) error {
+	sealed := w.aead.Seal(nil, chunkNonce(w.aead, w.counter), chunk, chunkAAD(final))
+	w.counter++
+
+	var size [4]byte
+	binary.BigEndian.PutUint32(size[:], uint32(len(sealed)))
+	if _, err := w.dst.Write(size[:]); err != nil {
+		return err
+	}
+	_, err := w.dst.Write(sealed)
+	return err
+}
+
+// decryptReader 分块解密读取器
+type decryptReader struct {
+	src     *bufio.Reader
+	aead    cipher.AEAD
+	buf     []byte
+	counter uint64
+	done    bool
+}
+
+// NewDecryptReader 读取加密流头部并按其中的密钥版本创建解密读取器
+//
+// 任一分块认证失败或流在最后一个分块之前结束时返回错误
+func (k *Keyring) NewDecryptReader(src io.Reader) (io.Reader, error) {
+	reader := bufio.NewReaderSize(src, maxSealedChunk+4)
+	magic := make([]byte, len(streamMagic))
+	if _, err := io.ReadFull(reader, magic); err != nil || string(magic) != streamMagic {
+		return nil, fmt.Errorf("不是有效的备份文件")
+	}
+	line, err := reader.ReadBytes('\n')
+	if err != nil {
+		return nil, fmt.Errorf("读取备份头部失败: %w", err)
+	}
+	var header streamHeader
+	if err := json.Unmarshal(line, &header); err != nil {
+		return nil, fmt.Errorf("解析备份头部失败: %w", err)
+	}
+	salt, err := base64.StdEncoding.DecodeString(header.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("解析备份头部失败: %w", err)
+	}
+	aead, err := k.aead(header.KeyVersion, salt)
+	if err != nil {
+		return nil, err
+	}
+	return &decryptReader{src: reader, aead: aead}, nil
+}
+
+// Read 按需读取并解密下一个分块
+func (r *decryptReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// open 读取并解密一个分块，之后没有数据时按最后一个分块认证
+func (r *decryptReader) open() error {
+	var size [4]byte
+	if _, err := io.ReadFull(r.src, size[:]); err != nil {
+		return fmt.Errorf("备份文件不完整: %w", err)
+	}
+	length := binary.BigEndian.Uint32(size[:])
+	if length > maxSealedChunk {
+		return fmt.Errorf("备份文件分块长度异常: %d", length)
+	}
+	sealed := make([]byte, length)
+	if _, err := io.ReadFull(r.src, sealed); err != nil {
+		return fmt.Errorf("备份文件不完整: %w", err)
+	}
+
+	_, peekErr := r.src.Peek(1)
+	final := errors.Is(peekErr, io.EOF)
+	if peekErr != nil && !final {
+		return peekErr
+	}
+
+	plain, err := r.aead.Open(nil, chunkNonce(r.aead, r.counter), sealed, chunkAAD(final))
+	if err != nil {
+		return fmt.Errorf("备份文件校验失败(密钥错误或内容被篡改): %w", err)
+	}
+	r.counter++
+	r.buf = plain
+	r.done = final
+	return nil
+}
+
+// chunkNonce 以分块序号作为nonce，每个备份的数据密钥不同，nonce不会重复
+func chunkNonce(aead cipher.AEAD, counter uint64) []byte {
+	nonce := make([]byte, aead.NonceSize())
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], counter)
+	return nonce
+}
+
+// chunkAAD 分块附加数据，标记是否为最后一个分块
+func chunkAAD(final bool) []byte {
+	if final {
+		return []byte{1}
+	}
+	return []byte{0}
+}
@@ -0,0 +1,114 @@
+// Package buildinfo 提供构建元数据(版本、Git提交、构建时间、Go版本)
+//
+// 发布构建时通过ldflags注入：
+//
+//	go build -ldflags "\
+//	  -X cloudpan/internal/pkg/buildinfo.Version=v1.4.0 \
+//	  -X cloudpan/internal/pkg/buildinfo.GitCommit=$(git rev-parse HEAD) \
+//	  -X cloudpan/internal/pkg/buildinfo.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./cmd
+//
+// 未注入时从Go工具链写入二进制的VCS信息中读取提交和时间，本地 go run 时均为空
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// 通过ldflags注入的构建信息
+var (
+	Version   = ""
+	GitCommit = ""
+	BuildTime = ""
+)
+
+// devVersion 未注入版本号时使用的版本
+const devVersion = "dev"
+
+// Info 构建元数据
+type Info struct {
+	Version   string `json:"version"`    // 发布版本
+	GitCommit string `json:"git_commit"` // Git提交哈希
+	BuildTime string `json:"build_time"` // 构建时间(RFC3339)
+	GoVersion string `json:"go_version"` // 编译使用的Go版本
+	Platform  string `json:"platform"`   // 运行平台 GOOS/GOARCH
+	Modified  bool   `json:"modified"`   // 构建时工作区是否有未提交的修改
+}
+
+var (
+	infoOnce sync.Once
+	info     Info
+)
+
+// Get 返回当前二进制的构建信息，结果在首次调用后缓存
+func Get() Info {
+	infoOnce.Do(func() {
+		info = resolve(Version, GitCommit, BuildTime, readBuildInfo)
+	})
+	return info
+}
+
+// ShortCommit 返回12位的短提交哈希，便于在日志和页面中展示
+func (i Info) ShortCommit() string {
+	if len(i.GitCommit) > 12 {
+		return i.GitCommit[:12]
+	}
+	return i.GitCommit
+}
+
+// Fields 返回用于结构化日志的构建信息字段，便于将日志和错误报告关联到具体发布
+func Fields() []zap.Field {
+	i := Get()
+	return []zap.Field{
+		zap.String("build_version", i.Version),
+		zap.String("build_commit", i.ShortCommit()),
+		zap.String("build_time", i.BuildTime),
+	}
+}
+
+// readBuildInfo 读取Go工具链写入的构建信息
+func readBuildInfo() (*debug.BuildInfo, bool) {
+	return debug.ReadBuildInfo()
+}
+
+// resolve 合并ldflags注入值与VCS信息，ldflags优先
+func resolve(version, commit, buildTime string, read func() (*debug.BuildInfo, bool)) Info {
+	result := Info{
+		Version:   version,
+		GitCommit: commit,
+		BuildTime: buildTime,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+
+	if bi, ok := read(); ok {
+		if bi.GoVersion != "" {
+			result.GoVersion = bi.GoVersion
+		}
+		if result.Version == "" && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+			result.Version = bi.Main.Version
+		}
+		for _, setting := range bi.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if result.GitCommit == "" {
+					result.GitCommit = setting.Value
+				}
+			case "vcs.time":
+				if result.BuildTime == "" {
+					result.BuildTime = setting.Value
+				}
+			case "vcs.modified":
+				result.Modified = setting.Value == "true"
+			}
+		}
+	}
+
+	if result.Version == "" {
+		result.Version = devVersion
+	}
+	return result
+}
@@ -0,0 +1,47 @@
+package buildinfo
+
+import (
+	"runtime/debug"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolve(t *testing.T) {
+	vcs := func() (*debug.BuildInfo, bool) {
+		return &debug.BuildInfo{
+			GoVersion: "go1.23.4",
+			Main:      debug.Module{Version: "(devel)"},
+			Settings: []debug.BuildSetting{
+				{Key: "vcs.revision", Value: "0123456789abcdef0123"},
+				{Key: "vcs.time", Value: "2024-06-01T08:00:00Z"},
+				{Key: "vcs.modified", Value: "true"},
+			},
+		}, true
+	}
+
+	t.Run("ldflags take precedence", func(t *testing.T) {
+		info := resolve("v1.4.0", "feedbeef", "2024-07-01T00:00:00Z", vcs)
+		assert.Equal(t, "v1.4.0", info.Version)
+		assert.Equal(t, "feedbeef", info.GitCommit)
+		assert.Equal(t, "2024-07-01T00:00:00Z", info.BuildTime)
+		assert.Equal(t, "go1.23.4", info.GoVersion)
+		assert.True(t, info.Modified)
+	})
+
+	t.Run("fallback to vcs info", func(t *testing.T) {
+		info := resolve("", "", "", vcs)
+		assert.Equal(t, devVersion, info.Version)
+		assert.Equal(t, "0123456789abcdef0123", info.GitCommit)
+		assert.Equal(t, "0123456789ab", info.ShortCommit())
+		assert.Equal(t, "2024-06-01T08:00:00Z", info.BuildTime)
+	})
+
+	t.Run("no build info", func(t *testing.T) {
+		info := resolve("", "", "", func() (*debug.BuildInfo, bool) { return nil, false })
+		assert.Equal(t, devVersion, info.Version)
+		assert.Empty(t, info.GitCommit)
+		assert.NotEmpty(t, info.GoVersion)
+		assert.NotEmpty(t, info.Platform)
+	})
+}
@@ -384,6 +384,9 @@ func (s *CacheTestSuite) TestKeyBuilder() {
 	fileID := "file456"
 	assert.Equal(s.T(), "file:file456", kb.FileInfo(fileID))
 	assert.Equal(s.T(), "share:token789", kb.FileShare("token789"))
+	assert.Equal(s.T(), "share:count:download:3", kb.ShareCounter("download", "3"))
+	assert.Equal(s.T(), "share:meta:token789:2", kb.ShareMeta("token789", 2))
+	assert.Equal(s.T(), "share:meta:ver:token789", kb.ShareMetaVersion("token789"))
 	assert.Equal(s.T(), "chunk:upload123:1", kb.FileChunk("upload123", 1))
 
 	// 测试验证码相关键
@@ -731,6 +734,7 @@ func (s *CacheTestSuite) TestTTLManagerExtended() {
 	assert.Equal(s.T(), 10*time.Minute, ttlManager.GetTTL("lock"))
 	assert.Equal(s.T(), 15*time.Minute, ttlManager.GetTTL("search_result"))
 	assert.Equal(s.T(), 24*time.Hour, ttlManager.GetTTL("search_history"))
+	assert.Equal(s.T(), 24*time.Hour, ttlManager.GetTTL("clipboard"))
 	assert.Equal(s.T(), 10*time.Minute, ttlManager.GetTTL("stats_user"))
 	assert.Equal(s.T(), 5*time.Minute, ttlManager.GetTTL("stats_file"))
 	assert.Equal(s.T(), 1*time.Minute, ttlManager.GetTTL("stats_system"))
@@ -949,6 +953,7 @@ func (s *CacheTestSuite) TestComplexKeyBuilder() {
 	assert.Equal(s.T(), "search:index:file", kb.SearchIndex(indexType))
 	assert.Equal(s.T(), "search:result:hash123", kb.SearchResult(queryHash))
 	assert.Equal(s.T(), "search:history:user789", kb.SearchHistory(userID))
+	assert.Equal(s.T(), "clipboard:user789", kb.Clipboard(userID))
 
 	// 测试更多文件相关键
 	uploadID := "upload123"
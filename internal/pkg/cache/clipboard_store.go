@@ -0,0 +1,177 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Clipboard 用户剪贴板内容
+type Clipboard struct {
+	Operation string     `json:"operation"`            // 操作类型(copy/move)
+	FileIDs   []uint64   `json:"file_ids"`             // 剪切或复制的文件ID，按加入顺序
+	UpdatedAt time.Time  `json:"updated_at"`           // 写入时间
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // 过期时间，不过期时为空
+}
+
+// ClipboardStore 用户剪贴板存储
+//
+// 每个用户一份剪贴板，写入时整体覆盖；超过 clipboard TTL 未重新写入时过期，
+// 保存在Redis中时可在不同设备和会话间使用
+//
+// 使用示例：
+//
+//	store := cache.NewRedisClipboardStore(cache.RedisClient, ttl)
+//	err := store.Set(ctx, userID, &cache.Clipboard{Operation: "move", FileIDs: []uint64{1, 2}, UpdatedAt: time.Now()})
+//	clipboard, err := store.Get(ctx, userID) // 为空或已过期时返回nil
+//	err = store.Clear(ctx, userID)
+type ClipboardStore interface {
+	// Get 返回用户的剪贴板，为空或已过期时返回nil
+	Get(ctx context.Context, userID uint64) (*Clipboard, error)
+	// Set 覆盖用户的剪贴板，ExpiresAt 由存储根据TTL设置
+	Set(ctx context.Context, userID uint64, clipboard *Clipboard) error
+	// Clear 清空用户的剪贴板
+	Clear(ctx context.Context, userID uint64) error
+}
+
+// MemoryClipboardStore 进程内剪贴板存储，用于单实例部署和测试
+type MemoryClipboardStore struct {
+	mu         sync.Mutex
+	now        func() time.Time
+	ttl        time.Duration
+	clipboards map[uint64]Clipboard
+}
+
+// NewMemoryClipboardStore 创建进程内剪贴板存储，ttl为0时剪贴板不过期
+func NewMemoryClipboardStore(ttl time.Duration) *MemoryClipboardStore {
+	return &MemoryClipboardStore{
+		now:        time.Now,
+		ttl:        ttl,
+		clipboards: make(map[uint64]Clipboard),
+	}
+}
+
+// Get 返回用户的剪贴板
+func (s *MemoryClipboardStore) Get(_ context.Context, userID uint64) (*Clipboard, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	clipboard, ok := s.clipboards[userID]
+	if !ok {
+		return nil, nil
+	}
+	if clipboard.ExpiresAt != nil && !clipboard.ExpiresAt.After(s.now()) {
+		delete(s.clipboards, userID)
+		return nil, nil
+	}
+	clipboard.FileIDs = append([]uint64(nil), clipboard.FileIDs...)
+	return &clipboard, nil
+}
+
+// Set 覆盖用户的剪贴板
+func (s *MemoryClipboardStore) Set(_ context.Context, userID uint64, clipboard *Clipboard) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := *clipboard
+	stored.FileIDs = append([]uint64(nil), clipboard.FileIDs...)
+	stored.ExpiresAt = clipboardExpiry(s.now(), s.ttl)
+	s.clipboards[userID] = stored
+	clipboard.ExpiresAt = stored.ExpiresAt
+	return nil
+}
+
+// Clear 清空用户的剪贴板
+func (s *MemoryClipboardStore) Clear(_ context.Context, userID uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.clipboards, userID)
+	return nil
+}
+
+// clipboardExpiry 计算剪贴板的过期时间，ttl为0时返回nil
+func clipboardExpiry(now time.Time, ttl time.Duration) *time.Time {
+	if ttl <= 0 {
+		return nil
+	}
+	expiresAt := now.Add(ttl)
+	return &expiresAt
+}
+
+// RedisClipboardStore 基于Redis的剪贴板存储，多实例部署时共享剪贴板
+//
+// 每个用户一个字符串键，值为剪贴板的JSON
+type RedisClipboardStore struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+// NewRedisClipboardStore 创建Redis剪贴板存储，ttl为0时剪贴板不过期
+func NewRedisClipboardStore(client *redis.Client, ttl time.Duration) *RedisClipboardStore {
+	return &RedisClipboardStore{
+		client: client,
+		ttl:    ttl,
+	}
+}
+
+// Get 返回用户的剪贴板
+func (s *RedisClipboardStore) Get(ctx context.Context, userID uint64) (*Clipboard, error) {
+	data, err := s.client.Get(ctx, Keys.Clipboard(strconv.FormatUint(userID, 10))).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取剪贴板失败: %w", err)
+	}
+	var clipboard Clipboard
+	if err := json.Unmarshal(data, &clipboard); err != nil {
+		return nil, fmt.Errorf("解析剪贴板失败: %w", err)
+	}
+	return &clipboard, nil
+}
+
+// Set 覆盖用户的剪贴板
+func (s *RedisClipboardStore) Set(ctx context.Context, userID uint64, clipboard *Clipboard) error {
+	clipboard.ExpiresAt = clipboardExpiry(time.Now(), s.ttl)
+	data, err := json.Marshal(clipboard)
+	if err != nil {
+		return fmt.Errorf("序列化剪贴板失败: %w", err)
+	}
+	if err := s.client.Set(ctx, Keys.Clipboard(strconv.FormatUint(userID, 10)), data, s.ttl).Err(); err != nil {
+		return fmt.Errorf("保存剪贴板失败: %w", err)
+	}
+	return nil
+}
+
+// Clear 清空用户的剪贴板
+func (s *RedisClipboardStore) Clear(ctx context.Context, userID uint64) error {
+	if err := s.client.Del(ctx, Keys.Clipboard(strconv.FormatUint(userID, 10))).Err(); err != nil {
+		return fmt.Errorf("清空剪贴板失败: %w", err)
+	}
+	return nil
+}
+
+var (
+	defaultClipboardStoreMu sync.RWMutex
+	defaultClipboardStore   ClipboardStore
+)
+
+// SetDefaultClipboardStore 设置全局剪贴板存储，启动时调用
+func SetDefaultClipboardStore(store ClipboardStore) {
+	defaultClipboardStoreMu.Lock()
+	defer defaultClipboardStoreMu.Unlock()
+	defaultClipboardStore = store
+}
+
+// DefaultClipboardStore 返回全局剪贴板存储，未设置时返回nil
+func DefaultClipboardStore() ClipboardStore {
+	defaultClipboardStoreMu.RLock()
+	defer defaultClipboardStoreMu.RUnlock()
+	return defaultClipboardStore
+}
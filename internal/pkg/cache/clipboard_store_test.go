@@ -0,0 +1,59 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryClipboardStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryClipboardStore(time.Hour)
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	clipboard, err := store.Get(ctx, 7)
+	require.NoError(t, err)
+	assert.Nil(t, clipboard)
+
+	ids := []uint64{3, 1}
+	written := &Clipboard{Operation: "move", FileIDs: ids, UpdatedAt: now}
+	require.NoError(t, store.Set(ctx, 7, written))
+	require.NotNil(t, written.ExpiresAt)
+	assert.Equal(t, now.Add(time.Hour), *written.ExpiresAt)
+	// 写入后修改调用方的切片不影响已保存的内容
+	ids[0] = 9
+
+	clipboard, err = store.Get(ctx, 7)
+	require.NoError(t, err)
+	require.NotNil(t, clipboard)
+	assert.Equal(t, "move", clipboard.Operation)
+	assert.Equal(t, []uint64{3, 1}, clipboard.FileIDs)
+
+	// 其他用户的剪贴板互不影响
+	clipboard, err = store.Get(ctx, 8)
+	require.NoError(t, err)
+	assert.Nil(t, clipboard)
+
+	// 再次写入整体覆盖
+	require.NoError(t, store.Set(ctx, 7, &Clipboard{Operation: "copy", FileIDs: []uint64{5}, UpdatedAt: now}))
+	clipboard, err = store.Get(ctx, 7)
+	require.NoError(t, err)
+	assert.Equal(t, "copy", clipboard.Operation)
+	assert.Equal(t, []uint64{5}, clipboard.FileIDs)
+
+	require.NoError(t, store.Clear(ctx, 7))
+	clipboard, err = store.Get(ctx, 7)
+	require.NoError(t, err)
+	assert.Nil(t, clipboard)
+
+	// 超过TTL未重新写入时过期
+	require.NoError(t, store.Set(ctx, 9, &Clipboard{Operation: "copy", FileIDs: []uint64{1}, UpdatedAt: now}))
+	now = now.Add(2 * time.Hour)
+	clipboard, err = store.Get(ctx, 9)
+	require.NoError(t, err)
+	assert.Nil(t, clipboard)
+}
@@ -0,0 +1,313 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+)
+
+// InvalidationKind 失效消息类型，决定由哪些订阅者处理
+type InvalidationKind string
+
+// 失效消息类型
+const (
+	InvalidationReferenceData InvalidationKind = "reference_data" // 预热的参考数据，Targets为数据源名称
+	InvalidationFeatureFlags  InvalidationKind = "feature_flags"  // 特性开关本地缓存，Targets为特性键(为空表示全部)
+)
+
+// Invalidation 缓存失效消息
+type Invalidation struct {
+	Kind     InvalidationKind `json:"kind"`              // 消息类型
+	Targets  []string         `json:"targets,omitempty"` // 失效的对象，含义由消息类型决定，为空表示该类型的全部缓存
+	Origin   string           `json:"origin"`            // 发布消息的实例ID
+	IssuedAt time.Time        `json:"issued_at"`         // 发布时间
+}
+
+// InvalidationHandler 失效消息处理函数，需要幂等
+type InvalidationHandler func(ctx context.Context, msg *Invalidation)
+
+// InvalidationTransport 失效消息的跨实例传输通道
+type InvalidationTransport interface {
+	// Publish 向所有实例广播消息
+	Publish(ctx context.Context, payload []byte) error
+	// Subscribe 持续接收消息直到ctx结束
+	Subscribe(ctx context.Context, deliver func(payload []byte)) error
+}
+
+// InvalidationBus 跨实例缓存失效总线
+//
+// 多实例部署时，某个实例修改数据后发布失效消息，所有实例(包括发布者自身)
+// 的订阅者清除对应的进程内缓存，避免其他节点继续使用旧数据。
+// 消息先同步分发给本实例的订阅者，再通过传输通道广播；
+// 收到自己发布的消息时跳过，避免重复处理。
+// 传输通道为nil时只在本实例内分发，适用于单实例部署和测试
+//
+// 使用示例：
+//
+//	bus := cache.NewInvalidationBus(cache.NewRedisInvalidationTransport(cache.RedisClient), logger)
+//	bus.Subscribe(cache.InvalidationReferenceData, func(ctx context.Context, msg *cache.Invalidation) {
+//		warmer.Evict(msg.Targets...)
+//	})
+//	go bus.Run(ctx)
+//	err := bus.Publish(ctx, cache.InvalidationReferenceData, "plans")
+type InvalidationBus struct {
+	transport  InvalidationTransport
+	instanceID string
+	logger     *zap.Logger
+	now        func() time.Time
+
+	mu       sync.RWMutex
+	handlers map[InvalidationKind][]InvalidationHandler
+}
+
+// NewInvalidationBus 创建缓存失效总线
+//
+// transport 可以为nil(如Redis未初始化)，此时消息只在本实例内分发
+func NewInvalidationBus(transport InvalidationTransport, logger *zap.Logger) *InvalidationBus {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &InvalidationBus{
+		transport:  transport,
+		instanceID: newInstanceID(),
+		logger:     logger,
+		now:        time.Now,
+		handlers:   make(map[InvalidationKind][]InvalidationHandler),
+	}
+}
+
+// InstanceID 返回本实例ID
+func (b *InvalidationBus) InstanceID() string {
+	return b.instanceID
+}
+
+// CrossInstance 是否向其他实例广播失效消息
+func (b *InvalidationBus) CrossInstance() bool {
+	return b.transport != nil
+}
+
+// Subscribe 订阅指定类型的失效消息
+func (b *InvalidationBus) Subscribe(kind InvalidationKind, handler InvalidationHandler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[kind] = append(b.handlers[kind], handler)
+}
+
+// Publish 发布失效消息
+//
+// 本实例的订阅者同步处理后再广播给其他实例，广播失败时返回错误，本实例已完成失效
+func (b *InvalidationBus) Publish(ctx context.Context, kind InvalidationKind, targets ...string) error {
+	msg := &Invalidation{
+		Kind:     kind,
+		Targets:  targets,
+		Origin:   b.instanceID,
+		IssuedAt: b.now(),
+	}
+	b.dispatch(ctx, msg)
+
+	if b.transport == nil {
+		return nil
+	}
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("序列化失效消息失败: %w", err)
+	}
+	if err := b.transport.Publish(ctx, payload); err != nil {
+		return fmt.Errorf("广播失效消息失败: %w", err)
+	}
+	return nil
+}
+
+// Run 接收其他实例发布的失效消息，阻塞直到ctx结束
+func (b *InvalidationBus) Run(ctx context.Context) error {
+	if b.transport == nil {
+		<-ctx.Done()
+		return nil
+	}
+	return b.transport.Subscribe(ctx, func(payload []byte) {
+		b.receive(ctx, payload)
+	})
+}
+
+// receive 处理传输通道收到的消息，跳过本实例发布的消息
+func (b *InvalidationBus) receive(ctx context.Context, payload []byte) {
+	var msg Invalidation
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		b.logger.Warn("Discarding malformed invalidation message", zap.Error(err))
+		return
+	}
+	if msg.Origin == b.instanceID {
+		return
+	}
+	b.dispatch(ctx, &msg)
+}
+
+// dispatch 将消息分发给本实例的订阅者，单个订阅者panic不影响其他订阅者
+func (b *InvalidationBus) dispatch(ctx context.Context, msg *Invalidation) {
+	b.mu.RLock()
+	handlers := append([]InvalidationHandler(nil), b.handlers[msg.Kind]...)
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					b.logger.Error("Invalidation handler panicked",
+						zap.String("kind", string(msg.Kind)),
+						zap.Any("panic", r))
+				}
+			}()
+			handler(ctx, msg)
+		}()
+	}
+}
+
+var (
+	defaultBusMu sync.RWMutex
+	defaultBus   *InvalidationBus
+)
+
+// SetDefaultInvalidationBus 设置全局缓存失效总线，启动时调用
+func SetDefaultInvalidationBus(bus *InvalidationBus) {
+	defaultBusMu.Lock()
+	defer defaultBusMu.Unlock()
+	defaultBus = bus
+}
+
+// DefaultInvalidationBus 返回全局缓存失效总线，未设置时返回nil
+func DefaultInvalidationBus() *InvalidationBus {
+	defaultBusMu.RLock()
+	defer defaultBusMu.RUnlock()
+	return defaultBus
+}
+
+// newInstanceID 生成实例ID，由主机名和随机后缀组成便于排查
+func newInstanceID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "unknown"
+	}
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return fmt.Sprintf("%s-%d", host, time.Now().UnixNano())
+	}
+	return host + "-" + hex.EncodeToString(suffix)
+}
+
+// NewDefaultInvalidationTransport 按Redis连接选择失效消息传输通道，启动时调用
+//
+// Redis已初始化时返回Redis发布/订阅通道，否则返回nil，总线只在本实例内分发
+func NewDefaultInvalidationTransport() InvalidationTransport {
+	if RedisClient == nil {
+		return nil
+	}
+	return NewRedisInvalidationTransport(RedisClient)
+}
+
+// RedisInvalidationTransport 基于Redis发布/订阅的失效消息传输通道
+type RedisInvalidationTransport struct {
+	client  *redis.Client
+	channel string
+}
+
+// NewRedisInvalidationTransport 创建Redis失效消息传输通道
+func NewRedisInvalidationTransport(client *redis.Client) *RedisInvalidationTransport {
+	return &RedisInvalidationTransport{
+		client:  client,
+		channel: Keys.InvalidationChannel(),
+	}
+}
+
+// Publish 向频道发布消息
+func (t *RedisInvalidationTransport) Publish(ctx context.Context, payload []byte) error {
+	return t.client.Publish(ctx, t.channel, payload).Err()
+}
+
+// Subscribe 订阅频道，连接断开时由客户端自动重连
+func (t *RedisInvalidationTransport) Subscribe(ctx context.Context, deliver func(payload []byte)) error {
+	pubsub := t.client.Subscribe(ctx, t.channel)
+	defer pubsub.Close()
+
+	// 等待订阅确认，订阅失败时直接返回错误而不是静默丢失消息
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return fmt.Errorf("订阅失效频道失败: %w", err)
+	}
+
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-messages:
+			if !ok {
+				return nil
+			}
+			deliver([]byte(msg.Payload))
+		}
+	}
+}
+
+// MemoryInvalidationTransport 进程内失效消息传输通道，用于测试多实例场景
+//
+// 连接到同一个通道的多个总线相当于多个实例
+type MemoryInvalidationTransport struct {
+	mu          sync.RWMutex
+	subscribers []chan []byte
+}
+
+// NewMemoryInvalidationTransport 创建进程内传输通道
+func NewMemoryInvalidationTransport() *MemoryInvalidationTransport {
+	return &MemoryInvalidationTransport{}
+}
+
+// Publish 将消息投递给所有订阅者
+func (t *MemoryInvalidationTransport) Publish(ctx context.Context, payload []byte) error {
+	t.mu.RLock()
+	subscribers := append([]chan []byte(nil), t.subscribers...)
+	t.mu.RUnlock()
+
+	for _, ch := range subscribers {
+		select {
+		case ch <- payload:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// Subscribe 接收消息直到ctx结束
+func (t *MemoryInvalidationTransport) Subscribe(ctx context.Context, deliver func(payload []byte)) error {
+	ch := make(chan []byte, 16)
+	t.mu.Lock()
+	t.subscribers = append(t.subscribers, ch)
+	t.mu.Unlock()
+
+	defer func() {
+		t.mu.Lock()
+		for i, sub := range t.subscribers {
+			if sub == ch {
+				t.subscribers = append(t.subscribers[:i], t.subscribers[i+1:]...)
+				break
+			}
+		}
+		t.mu.Unlock()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case payload := <-ch:
+			deliver(payload)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// invalidationRecorder 记录收到的失效消息
+type invalidationRecorder struct {
+	mu       sync.Mutex
+	received []*Invalidation
+}
+
+func (r *invalidationRecorder) handle(_ context.Context, msg *Invalidation) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.received = append(r.received, msg)
+}
+
+func (r *invalidationRecorder) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.received)
+}
+
+// subscriberCount 返回进程内传输通道当前的订阅者数量
+func (t *MemoryInvalidationTransport) subscriberCount() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return len(t.subscribers)
+}
+
+func TestInvalidationBus_LocalOnly(t *testing.T) {
+	bus := NewInvalidationBus(nil, nil)
+	flags := &invalidationRecorder{}
+	refs := &invalidationRecorder{}
+	bus.Subscribe(InvalidationFeatureFlags, flags.handle)
+	bus.Subscribe(InvalidationReferenceData, refs.handle)
+
+	require.NoError(t, bus.Publish(context.Background(), InvalidationReferenceData, "plans"))
+	assert.Equal(t, 0, flags.count())
+	require.Equal(t, 1, refs.count())
+	assert.Equal(t, []string{"plans"}, refs.received[0].Targets)
+	assert.Equal(t, bus.InstanceID(), refs.received[0].Origin)
+	assert.False(t, bus.CrossInstance())
+}
+
+func TestNewDefaultInvalidationTransport(t *testing.T) {
+	original := RedisClient
+	defer func() { RedisClient = original }()
+
+	RedisClient = nil
+	assert.Nil(t, NewDefaultInvalidationTransport(), "Redis未初始化时只在本实例内分发")
+
+	RedisClient = redis.NewClient(&redis.Options{Addr: "127.0.0.1:0"})
+	defer RedisClient.Close()
+	assert.IsType(t, &RedisInvalidationTransport{}, NewDefaultInvalidationTransport())
+	assert.True(t, NewInvalidationBus(NewDefaultInvalidationTransport(), nil).CrossInstance())
+}
+
+func TestInvalidationBus_CrossInstance(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	transport := NewMemoryInvalidationTransport()
+	nodeA := NewInvalidationBus(transport, nil)
+	nodeB := NewInvalidationBus(transport, nil)
+	require.NotEqual(t, nodeA.InstanceID(), nodeB.InstanceID())
+
+	recorderA := &invalidationRecorder{}
+	recorderB := &invalidationRecorder{}
+	nodeA.Subscribe(InvalidationFeatureFlags, recorderA.handle)
+	nodeB.Subscribe(InvalidationFeatureFlags, recorderB.handle)
+
+	go nodeA.Run(ctx)
+	go nodeB.Run(ctx)
+	require.Eventually(t, func() bool { return transport.subscriberCount() == 2 }, time.Second, 5*time.Millisecond)
+
+	require.NoError(t, nodeA.Publish(ctx, InvalidationFeatureFlags, "new_editor"))
+
+	require.Eventually(t, func() bool { return recorderB.count() == 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, nodeA.InstanceID(), recorderB.received[0].Origin)
+
+	// 发布者自身只处理一次，不会因为收到自己广播的消息而重复处理
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, 1, recorderA.count())
+}
+
+func TestInvalidationBus_ReceiveIsolation(t *testing.T) {
+	bus := NewInvalidationBus(nil, nil)
+	recorder := &invalidationRecorder{}
+	bus.Subscribe(InvalidationFeatureFlags, func(context.Context, *Invalidation) { panic("boom") })
+	bus.Subscribe(InvalidationFeatureFlags, recorder.handle)
+
+	bus.receive(context.Background(), []byte("not json"))
+	bus.receive(context.Background(), []byte(`{"kind":"feature_flags","origin":"other-node"}`))
+	assert.Equal(t, 1, recorder.count())
+
+	// 自己发布的消息被跳过
+	bus.receive(context.Background(), []byte(`{"kind":"feature_flags","origin":"`+bus.InstanceID()+`"}`))
+	assert.Equal(t, 1, recorder.count())
+}
+
+func TestWarmer_Evict(t *testing.T) {
+	ctx := context.Background()
+	plans := &countingLoader{value: "plans"}
+	policies := &countingLoader{value: "policies"}
+
+	warmer := NewWarmer(nil, nil)
+	warmer.Register(WarmSource{Name: "plans", TTL: time.Hour, Load: plans.load})
+	warmer.Register(WarmSource{Name: "policies", TTL: time.Hour, Load: policies.load})
+	warmer.WarmAll(ctx)
+
+	warmer.Evict("plans")
+	warmer.Get(ctx, "plans")
+	warmer.Get(ctx, "policies")
+	assert.Equal(t, 2, plans.calls)
+	assert.Equal(t, 1, policies.calls)
+
+	// 不指定数据源时清除全部本地缓存
+	warmer.Evict()
+	warmer.Get(ctx, "policies")
+	assert.Equal(t, 2, policies.calls)
+}
@@ -5,19 +5,30 @@ import "fmt"
 // 缓存键命名规范常量
 const (
 	// 用户相关
-	KeyUserSession     = "session:%s"     // session:token
-	KeyUserPermissions = "permissions:%s" // permissions:user_id
-	KeyUserProfile     = "profile:%s"     // profile:user_id
-	KeyUserOnline      = "online:%s"      // online:user_id
-	KeyUserQuota       = "quota:%s"       // quota:user_id
+	KeyUserSession     = "session:%s"      // session:token
+	KeyUserSessions    = "sessions:%s"     // sessions:user_id
+	KeyUserPermissions = "permissions:%s"  // permissions:user_id
+	KeyUserProfile     = "profile:%s"      // profile:user_id
+	KeyUserOnline      = "online:%s"       // online:user_id
+	KeyUserQuota       = "quota:%s"        // quota:user_id
+	KeyUserLimits      = "limits:%s"       // limits:user_id
+	KeyUserRevocation  = "revoked:%s"      // revoked:user_id
+	KeyUserRefresh     = "refresh:user:%s" // refresh:user:user_id
+	KeyRefreshToken    = "refresh:jti:%s"  // refresh:jti:jti
+	KeyLoginChallenge  = "mfa:%s"          // mfa:challenge_id
+	KeySSOState        = "sso:state:%s"    // sso:state:state
+	KeyMergeToken      = "merge:%s"        // merge:token
 
 	// 文件相关
-	KeyFileInfo     = "file:%s"     // file:file_id
-	KeyFileShare    = "share:%s"    // share:token
-	KeyFileUpload   = "upload:%s"   // upload:upload_id
-	KeyFileChunk    = "chunk:%s:%d" // chunk:upload_id:chunk_num
-	KeyFilePreview  = "preview:%s"  // preview:file_id
-	KeyFileDownload = "download:%s" // download:file_id
+	KeyFileInfo     = "file:%s"           // file:file_id
+	KeyFileShare    = "share:%s"          // share:token
+	KeyFileUpload   = "upload:%s"         // upload:upload_id
+	KeyFileChunk    = "chunk:%s:%d"       // chunk:upload_id:chunk_num
+	KeyFilePreview  = "preview:%s"        // preview:file_id
+	KeyFileDownload = "download:%s"       // download:file_id
+	KeyShareCounter = "share:count:%s:%s" // share:count:kind:share_id
+	KeyShareMeta    = "share:meta:%s:%d"  // share:meta:share_code:version
+	KeyShareMetaVer = "share:meta:ver:%s" // share:meta:ver:share_code
 
 	// 团队相关
 	KeyTeamInfo        = "team:%s"          // team:team_id
@@ -40,6 +51,7 @@ const (
 	KeyUserLock   = "lock:user:%s"   // lock:user:user_id
 	KeyTeamLock   = "lock:team:%s"   // lock:team:team_id
 	KeyUploadLock = "lock:upload:%s" // lock:upload:upload_id
+	KeyTaskLock   = "lock:task:%s"   // lock:task:task_name
 
 	// 队列相关
 	KeyTaskQueue   = "queue:task"   // 任务队列
@@ -63,6 +75,15 @@ const (
 	KeySearchIndex   = "search:index:%s"   // search:index:type
 	KeySearchResult  = "search:result:%s"  // search:result:query_hash
 	KeySearchHistory = "search:history:%s" // search:history:user_id
+
+	// 剪贴板相关
+	KeyClipboard = "clipboard:%s" // clipboard:user_id
+
+	// 参考数据相关
+	KeyReferenceData = "ref:%s" // ref:source_name
+
+	// 发布订阅频道
+	KeyInvalidationChannel = "pubsub:invalidate" // 跨实例缓存失效频道
 )
 
 // KeyBuilder 缓存键构建器
@@ -83,6 +104,41 @@ func (kb *KeyBuilder) UserSession(token string) string {
 	return kb.build(KeyUserSession, token)
 }
 
+// UserSessions 生成用户登录会话集合缓存键
+func (kb *KeyBuilder) UserSessions(userID string) string {
+	return kb.build(KeyUserSessions, userID)
+}
+
+// UserRevocation 生成用户令牌吊销时间缓存键
+func (kb *KeyBuilder) UserRevocation(userID string) string {
+	return kb.build(KeyUserRevocation, userID)
+}
+
+// UserRefreshTokens 生成用户已登记刷新令牌集合缓存键
+func (kb *KeyBuilder) UserRefreshTokens(userID string) string {
+	return kb.build(KeyUserRefresh, userID)
+}
+
+// RefreshToken 生成刷新令牌登记缓存键
+func (kb *KeyBuilder) RefreshToken(jti string) string {
+	return kb.build(KeyRefreshToken, jti)
+}
+
+// LoginChallenge 生成登录两步验证挑战缓存键
+func (kb *KeyBuilder) LoginChallenge(id string) string {
+	return kb.build(KeyLoginChallenge, id)
+}
+
+// SSOState 生成单点登录请求缓存键
+func (kb *KeyBuilder) SSOState(state string) string {
+	return kb.build(KeySSOState, state)
+}
+
+// MergeToken 生成账户合并令牌缓存键
+func (kb *KeyBuilder) MergeToken(token string) string {
+	return kb.build(KeyMergeToken, token)
+}
+
 // UserPermissions 生成用户权限缓存键
 func (kb *KeyBuilder) UserPermissions(userID string) string {
 	return kb.build(KeyUserPermissions, userID)
@@ -103,6 +159,11 @@ func (kb *KeyBuilder) UserQuota(userID string) string {
 	return kb.build(KeyUserQuota, userID)
 }
 
+// UserLimits 生成用户有效限制缓存键
+func (kb *KeyBuilder) UserLimits(userID string) string {
+	return kb.build(KeyUserLimits, userID)
+}
+
 // FileInfo 生成文件信息缓存键
 func (kb *KeyBuilder) FileInfo(fileID string) string {
 	return kb.build(KeyFileInfo, fileID)
@@ -133,6 +194,21 @@ func (kb *KeyBuilder) FileDownload(fileID string) string {
 	return kb.build(KeyFileDownload, fileID)
 }
 
+// ShareCounter 生成分享访问/下载次数计数键
+func (kb *KeyBuilder) ShareCounter(kind, shareID string) string {
+	return kb.build(KeyShareCounter, kind, shareID)
+}
+
+// ShareMeta 生成分享公开元数据缓存键
+func (kb *KeyBuilder) ShareMeta(code string, version int64) string {
+	return kb.build(KeyShareMeta, code, version)
+}
+
+// ShareMetaVersion 生成分享公开元数据版本号键，分享更新或撤销时递增
+func (kb *KeyBuilder) ShareMetaVersion(code string) string {
+	return kb.build(KeyShareMetaVer, code)
+}
+
 // 团队相关键构建方法
 // TeamInfo 生成团队信息缓存键
 func (kb *KeyBuilder) TeamInfo(teamID string) string {
@@ -207,6 +283,11 @@ func (kb *KeyBuilder) UploadLock(uploadID string) string {
 	return kb.build(KeyUploadLock, uploadID)
 }
 
+// TaskLock 生成定期维护任务锁缓存键
+func (kb *KeyBuilder) TaskLock(taskName string) string {
+	return kb.build(KeyTaskLock, taskName)
+}
+
 // 消息相关键构建方法
 // Conversation 生成会话缓存键
 func (kb *KeyBuilder) Conversation(conversationID string) string {
@@ -265,5 +346,23 @@ func (kb *KeyBuilder) SearchHistory(userID string) string {
 	return kb.build(KeySearchHistory, userID)
 }
 
+// 剪贴板相关键构建方法
+// Clipboard 生成用户剪贴板缓存键
+func (kb *KeyBuilder) Clipboard(userID string) string {
+	return kb.build(KeyClipboard, userID)
+}
+
+// 参考数据相关键构建方法
+// ReferenceData 生成预热参考数据缓存键
+func (kb *KeyBuilder) ReferenceData(source string) string {
+	return kb.build(KeyReferenceData, source)
+}
+
+// 发布订阅频道相关键构建方法
+// InvalidationChannel 生成跨实例缓存失效频道名
+func (kb *KeyBuilder) InvalidationChannel() string {
+	return KeyInvalidationChannel
+}
+
 // Keys 全局键构建器实例
 var Keys = NewKeyBuilder()
@@ -0,0 +1,226 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ErrLoginChallengeNotFound 登录两步验证挑战不存在、已使用或已过期
+var ErrLoginChallengeNotFound = errors.New("login challenge not found")
+
+// LoginChallenge 登录两步验证挑战
+//
+// 用户通过密码验证后，已启用两步验证的账户先得到挑战，提交验证码后才签发令牌
+type LoginChallenge struct {
+	ID         string    `json:"id"`             // 挑战ID，作为挑战令牌返回给客户端
+	UserID     uint64    `json:"user_id"`        // 已通过密码验证的用户ID
+	RememberMe bool      `json:"remember_me"`    // 登录请求中的记住我选项
+	Role       string    `json:"role,omitempty"` // 通过验证后签发令牌的角色，为空时使用默认角色
+	Attempts   int       `json:"-"`              // 已失败的验证次数
+	ExpiresAt  time.Time `json:"expires_at"`     // 过期时间
+}
+
+// LoginChallengeStore 登录两步验证挑战存储
+//
+// 使用示例：
+//
+//	store := cache.NewRedisLoginChallengeStore(cache.RedisClient)
+//	err := store.Save(ctx, &cache.LoginChallenge{ID: id, UserID: userID, ExpiresAt: time.Now().Add(5 * time.Minute)})
+//	challenge, err := store.Get(ctx, id)
+//	attempts, err := store.RecordFailure(ctx, id)
+//	deleted, err := store.Delete(ctx, id)
+type LoginChallengeStore interface {
+	// Save 保存挑战，过期时间到达后自动删除
+	Save(ctx context.Context, challenge *LoginChallenge) error
+	// Get 读取挑战，不存在或已过期时返回 ErrLoginChallengeNotFound
+	Get(ctx context.Context, id string) (*LoginChallenge, error)
+	// RecordFailure 记录一次验证失败并返回累计失败次数，挑战不存在时返回 ErrLoginChallengeNotFound
+	RecordFailure(ctx context.Context, id string) (int, error)
+	// Delete 删除挑战，返回挑战是否存在；验证通过后删除，删除成功的请求才能签发令牌
+	Delete(ctx context.Context, id string) (bool, error)
+}
+
+// MemoryLoginChallengeStore 进程内登录挑战存储，用于单实例部署和测试
+type MemoryLoginChallengeStore struct {
+	mu         sync.Mutex
+	now        func() time.Time
+	challenges map[string]LoginChallenge
+}
+
+// NewMemoryLoginChallengeStore 创建进程内登录挑战存储
+func NewMemoryLoginChallengeStore() *MemoryLoginChallengeStore {
+	return &MemoryLoginChallengeStore{
+		now:        time.Now,
+		challenges: make(map[string]LoginChallenge),
+	}
+}
+
+// Save 保存挑战，写入时顺带清理已过期的挑战
+func (s *MemoryLoginChallengeStore) Save(_ context.Context, challenge *LoginChallenge) error {
+	if challenge.ID == "" {
+		return fmt.Errorf("挑战ID不能为空")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	for id, existing := range s.challenges {
+		if !existing.ExpiresAt.After(now) {
+			delete(s.challenges, id)
+		}
+	}
+	s.challenges[challenge.ID] = *challenge
+	return nil
+}
+
+// Get 读取挑战
+func (s *MemoryLoginChallengeStore) Get(_ context.Context, id string) (*LoginChallenge, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	challenge, ok := s.challenges[id]
+	if !ok || !challenge.ExpiresAt.After(s.now()) {
+		return nil, ErrLoginChallengeNotFound
+	}
+	return &challenge, nil
+}
+
+// RecordFailure 记录一次验证失败
+func (s *MemoryLoginChallengeStore) RecordFailure(_ context.Context, id string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	challenge, ok := s.challenges[id]
+	if !ok || !challenge.ExpiresAt.After(s.now()) {
+		return 0, ErrLoginChallengeNotFound
+	}
+	challenge.Attempts++
+	s.challenges[id] = challenge
+	return challenge.Attempts, nil
+}
+
+// Delete 删除挑战
+func (s *MemoryLoginChallengeStore) Delete(_ context.Context, id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	challenge, ok := s.challenges[id]
+	if !ok {
+		return false, nil
+	}
+	delete(s.challenges, id)
+	return challenge.ExpiresAt.After(s.now()), nil
+}
+
+// recordLoginChallengeFailureScript 挑战存在时累加失败次数，避免为已过期的挑战创建没有过期时间的键
+//
+// KEYS: 挑战键
+// 返回: 累计失败次数，挑战不存在时返回-1
+var recordLoginChallengeFailureScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return -1
+end
+return redis.call('HINCRBY', KEYS[1], 'attempts', 1)
+`)
+
+// RedisLoginChallengeStore 基于Redis的登录挑战存储，多实例部署时共享挑战
+//
+// 每个挑战一个哈希键：data 字段为挑战JSON，attempts 字段为失败次数，过期时间与挑战一致
+type RedisLoginChallengeStore struct {
+	client *redis.Client
+	now    func() time.Time
+}
+
+// NewRedisLoginChallengeStore 创建Redis登录挑战存储
+func NewRedisLoginChallengeStore(client *redis.Client) *RedisLoginChallengeStore {
+	return &RedisLoginChallengeStore{
+		client: client,
+		now:    time.Now,
+	}
+}
+
+// Save 保存挑战
+func (s *RedisLoginChallengeStore) Save(ctx context.Context, challenge *LoginChallenge) error {
+	if challenge.ID == "" {
+		return fmt.Errorf("挑战ID不能为空")
+	}
+	ttl := challenge.ExpiresAt.Sub(s.now())
+	if ttl <= 0 {
+		return fmt.Errorf("挑战已过期")
+	}
+	data, err := json.Marshal(challenge)
+	if err != nil {
+		return fmt.Errorf("序列化挑战失败: %w", err)
+	}
+
+	key := Keys.LoginChallenge(challenge.ID)
+	pipe := s.client.TxPipeline()
+	pipe.HSet(ctx, key, "data", data, "attempts", challenge.Attempts)
+	pipe.PExpire(ctx, key, ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("保存挑战失败: %w", err)
+	}
+	return nil
+}
+
+// Get 读取挑战
+func (s *RedisLoginChallengeStore) Get(ctx context.Context, id string) (*LoginChallenge, error) {
+	fields, err := s.client.HGetAll(ctx, Keys.LoginChallenge(id)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("读取挑战失败: %w", err)
+	}
+	data, ok := fields["data"]
+	if !ok {
+		return nil, ErrLoginChallengeNotFound
+	}
+	var challenge LoginChallenge
+	if err := json.Unmarshal([]byte(data), &challenge); err != nil {
+		return nil, ErrLoginChallengeNotFound
+	}
+	challenge.Attempts, _ = strconv.Atoi(fields["attempts"])
+	return &challenge, nil
+}
+
+// RecordFailure 记录一次验证失败
+func (s *RedisLoginChallengeStore) RecordFailure(ctx context.Context, id string) (int, error) {
+	attempts, err := recordLoginChallengeFailureScript.Run(ctx, s.client, []string{Keys.LoginChallenge(id)}).Int()
+	if err != nil {
+		return 0, fmt.Errorf("记录挑战失败次数失败: %w", err)
+	}
+	if attempts < 0 {
+		return 0, ErrLoginChallengeNotFound
+	}
+	return attempts, nil
+}
+
+// Delete 删除挑战
+func (s *RedisLoginChallengeStore) Delete(ctx context.Context, id string) (bool, error) {
+	deleted, err := s.client.Del(ctx, Keys.LoginChallenge(id)).Result()
+	if err != nil {
+		return false, fmt.Errorf("删除挑战失败: %w", err)
+	}
+	return deleted > 0, nil
+}
+
+var (
+	defaultLoginChallengeStoreMu sync.RWMutex
+	defaultLoginChallengeStore   LoginChallengeStore
+)
+
+// SetDefaultLoginChallengeStore 设置全局登录挑战存储，启动时调用
+func SetDefaultLoginChallengeStore(store LoginChallengeStore) {
+	defaultLoginChallengeStoreMu.Lock()
+	defer defaultLoginChallengeStoreMu.Unlock()
+	defaultLoginChallengeStore = store
+}
+
+// DefaultLoginChallengeStore 返回全局登录挑战存储，未设置时返回nil
+func DefaultLoginChallengeStore() LoginChallengeStore {
+	defaultLoginChallengeStoreMu.RLock()
+	defer defaultLoginChallengeStoreMu.RUnlock()
+	return defaultLoginChallengeStore
+}
@@ -0,0 +1,56 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryLoginChallengeStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryLoginChallengeStore()
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	require.NoError(t, store.Save(ctx, &LoginChallenge{ID: "a", UserID: 7, RememberMe: true, ExpiresAt: now.Add(5 * time.Minute)}))
+	assert.Error(t, store.Save(ctx, &LoginChallenge{UserID: 7}))
+
+	challenge, err := store.Get(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(7), challenge.UserID)
+	assert.True(t, challenge.RememberMe)
+	_, err = store.Get(ctx, "missing")
+	assert.ErrorIs(t, err, ErrLoginChallengeNotFound)
+
+	attempts, err := store.RecordFailure(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, 1, attempts)
+	attempts, err = store.RecordFailure(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, 2, attempts)
+	challenge, err = store.Get(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, 2, challenge.Attempts)
+
+	// 挑战只能删除一次
+	deleted, err := store.Delete(ctx, "a")
+	require.NoError(t, err)
+	assert.True(t, deleted)
+	deleted, err = store.Delete(ctx, "a")
+	require.NoError(t, err)
+	assert.False(t, deleted)
+
+	// 过期的挑战不可读取、计数或删除
+	require.NoError(t, store.Save(ctx, &LoginChallenge{ID: "b", UserID: 7, ExpiresAt: now.Add(time.Minute)}))
+	now = now.Add(2 * time.Minute)
+	_, err = store.Get(ctx, "b")
+	assert.ErrorIs(t, err, ErrLoginChallengeNotFound)
+	_, err = store.RecordFailure(ctx, "b")
+	assert.ErrorIs(t, err, ErrLoginChallengeNotFound)
+	deleted, err = store.Delete(ctx, "b")
+	require.NoError(t, err)
+	assert.False(t, deleted)
+}
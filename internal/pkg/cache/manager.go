@@ -53,6 +53,22 @@ func NewCacheManager() *CacheManager {
 	}
 }
 
+// WithContext 返回使用指定上下文的缓存管理器副本
+//
+// 请求中使用绑定了请求上下文的副本，请求取消时缓存操作随之取消，启用追踪时Redis命令的span挂在请求链路下
+//
+// 使用示例:
+//
+//	err := cm.WithContext(ctx).Get("user:123", &user)
+func (c *CacheManager) WithContext(ctx context.Context) *CacheManager {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	clone := *c
+	clone.ctx = ctx
+	return &clone
+}
+
 // getClient 获取Redis客户端（延迟初始化）
 //
 // 实现延迟初始化模式，仅在首次调用时创建Redis连接：
@@ -0,0 +1,187 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ErrMergeTokenNotFound 账户合并令牌不存在、已使用或已过期
+var ErrMergeTokenNotFound = errors.New("merge token not found")
+
+// MergeToken 账户合并令牌
+//
+// 用户在要合并掉的账户中申请，证明持有该账户；登录保留的账户后提交令牌完成合并
+type MergeToken struct {
+	Token     string    `json:"token"`      // 令牌，返回给客户端
+	UserID    uint64    `json:"user_id"`    // 申请令牌的账户ID(合并后停用)
+	ExpiresAt time.Time `json:"expires_at"` // 过期时间
+}
+
+// MergeTokenStore 账户合并令牌存储
+//
+// 使用示例：
+//
+//	store := cache.NewRedisMergeTokenStore(cache.RedisClient)
+//	err := store.Save(ctx, &cache.MergeToken{Token: token, UserID: userID, ExpiresAt: time.Now().Add(10 * time.Minute)})
+//	pending, err := store.Take(ctx, token)
+type MergeTokenStore interface {
+	// Save 保存令牌，过期时间到达后自动删除
+	Save(ctx context.Context, token *MergeToken) error
+	// Get 读取令牌但不删除，用于使用前的校验；不存在或已过期时返回 ErrMergeTokenNotFound
+	Get(ctx context.Context, token string) (*MergeToken, error)
+	// Take 取出并删除令牌，每个令牌只能使用一次；不存在或已过期时返回 ErrMergeTokenNotFound
+	Take(ctx context.Context, token string) (*MergeToken, error)
+}
+
+// MemoryMergeTokenStore 进程内账户合并令牌存储，用于单实例部署和测试
+type MemoryMergeTokenStore struct {
+	mu     sync.Mutex
+	now    func() time.Time
+	tokens map[string]MergeToken
+}
+
+// NewMemoryMergeTokenStore 创建进程内账户合并令牌存储
+func NewMemoryMergeTokenStore() *MemoryMergeTokenStore {
+	return &MemoryMergeTokenStore{
+		now:    time.Now,
+		tokens: make(map[string]MergeToken),
+	}
+}
+
+// Save 保存令牌，写入时顺带清理已过期的令牌
+func (s *MemoryMergeTokenStore) Save(_ context.Context, token *MergeToken) error {
+	if token.Token == "" {
+		return fmt.Errorf("合并令牌不能为空")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	for key, existing := range s.tokens {
+		if !existing.ExpiresAt.After(now) {
+			delete(s.tokens, key)
+		}
+	}
+	s.tokens[token.Token] = *token
+	return nil
+}
+
+// Get 读取令牌
+func (s *MemoryMergeTokenStore) Get(_ context.Context, key string) (*MergeToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	token, ok := s.tokens[key]
+	if !ok || !token.ExpiresAt.After(s.now()) {
+		return nil, ErrMergeTokenNotFound
+	}
+	return &token, nil
+}
+
+// Take 取出并删除令牌
+func (s *MemoryMergeTokenStore) Take(_ context.Context, key string) (*MergeToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	token, ok := s.tokens[key]
+	if !ok {
+		return nil, ErrMergeTokenNotFound
+	}
+	delete(s.tokens, key)
+	if !token.ExpiresAt.After(s.now()) {
+		return nil, ErrMergeTokenNotFound
+	}
+	return &token, nil
+}
+
+// RedisMergeTokenStore 基于Redis的账户合并令牌存储，多实例部署时共享令牌
+type RedisMergeTokenStore struct {
+	client *redis.Client
+	now    func() time.Time
+}
+
+// NewRedisMergeTokenStore 创建Redis账户合并令牌存储
+func NewRedisMergeTokenStore(client *redis.Client) *RedisMergeTokenStore {
+	return &RedisMergeTokenStore{
+		client: client,
+		now:    time.Now,
+	}
+}
+
+// Save 保存令牌
+func (s *RedisMergeTokenStore) Save(ctx context.Context, token *MergeToken) error {
+	if token.Token == "" {
+		return fmt.Errorf("合并令牌不能为空")
+	}
+	ttl := token.ExpiresAt.Sub(s.now())
+	if ttl <= 0 {
+		return fmt.Errorf("合并令牌已过期")
+	}
+	data, err := json.Marshal(token)
+	if err != nil {
+		return fmt.Errorf("序列化合并令牌失败: %w", err)
+	}
+	if err := s.client.Set(ctx, Keys.MergeToken(token.Token), data, ttl).Err(); err != nil {
+		return fmt.Errorf("保存合并令牌失败: %w", err)
+	}
+	return nil
+}
+
+// Get 读取令牌，过期的键已由Redis删除
+func (s *RedisMergeTokenStore) Get(ctx context.Context, key string) (*MergeToken, error) {
+	data, err := s.client.Get(ctx, Keys.MergeToken(key)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrMergeTokenNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取合并令牌失败: %w", err)
+	}
+	var token MergeToken
+	if err := json.Unmarshal(data, &token); err != nil {
+		return nil, ErrMergeTokenNotFound
+	}
+	return &token, nil
+}
+
+// Take 在同一事务中读取并删除令牌，并发提交只有一个能取到
+func (s *RedisMergeTokenStore) Take(ctx context.Context, key string) (*MergeToken, error) {
+	redisKey := Keys.MergeToken(key)
+	pipe := s.client.TxPipeline()
+	get := pipe.Get(ctx, redisKey)
+	pipe.Del(ctx, redisKey)
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("读取合并令牌失败: %w", err)
+	}
+	data, err := get.Bytes()
+	if err != nil {
+		return nil, ErrMergeTokenNotFound
+	}
+	var token MergeToken
+	if err := json.Unmarshal(data, &token); err != nil {
+		return nil, ErrMergeTokenNotFound
+	}
+	return &token, nil
+}
+
+var (
+	defaultMergeTokenStoreMu sync.RWMutex
+	defaultMergeTokenStore   MergeTokenStore
+)
+
+// SetDefaultMergeTokenStore 设置全局账户合并令牌存储，启动时调用
+func SetDefaultMergeTokenStore(store MergeTokenStore) {
+	defaultMergeTokenStoreMu.Lock()
+	defer defaultMergeTokenStoreMu.Unlock()
+	defaultMergeTokenStore = store
+}
+
+// DefaultMergeTokenStore 返回全局账户合并令牌存储，未设置时返回nil
+func DefaultMergeTokenStore() MergeTokenStore {
+	defaultMergeTokenStoreMu.RLock()
+	defer defaultMergeTokenStoreMu.RUnlock()
+	return defaultMergeTokenStore
+}
@@ -0,0 +1,41 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryMergeTokenStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryMergeTokenStore()
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	require.NoError(t, store.Save(ctx, &MergeToken{Token: "a", UserID: 7, ExpiresAt: now.Add(10 * time.Minute)}))
+	assert.Error(t, store.Save(ctx, &MergeToken{UserID: 7}))
+
+	// 读取不消耗令牌
+	token, err := store.Get(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(7), token.UserID)
+
+	token, err = store.Take(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(7), token.UserID)
+
+	// 每个令牌只能使用一次
+	_, err = store.Take(ctx, "a")
+	assert.ErrorIs(t, err, ErrMergeTokenNotFound)
+
+	// 过期的令牌不可使用
+	require.NoError(t, store.Save(ctx, &MergeToken{Token: "b", UserID: 7, ExpiresAt: now.Add(time.Minute)}))
+	now = now.Add(2 * time.Minute)
+	_, err = store.Get(ctx, "b")
+	assert.ErrorIs(t, err, ErrMergeTokenNotFound)
+	_, err = store.Take(ctx, "b")
+	assert.ErrorIs(t, err, ErrMergeTokenNotFound)
+}
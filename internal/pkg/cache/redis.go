@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"cloudpan/internal/pkg/config"
+	"cloudpan/internal/pkg/tracing"
 
 	"github.com/go-redis/redis/v8"
 )
@@ -39,11 +40,19 @@ func InitRedis() error {
 		IdleTimeout:  cfg.IdleTimeout,
 	})
 
+	// 启用追踪时为Redis命令创建span，需在 tracing.Init 之后初始化Redis
+	if tracing.Enabled() {
+		RedisClient.AddHook(TracingHook{})
+	}
+
 	// 测试连接
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	if err := RedisClient.Ping(ctx).Err(); err != nil {
+		// 连接失败时不保留客户端，调用方据此改用进程内存储
+		_ = RedisClient.Close()
+		RedisClient = nil
 		return fmt.Errorf("failed to connect to Redis: %w", err)
 	}
 
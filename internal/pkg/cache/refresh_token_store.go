@@ -0,0 +1,337 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// 刷新令牌状态
+const (
+	refreshTokenActive  = "active"  // 可用于换取新令牌
+	refreshTokenRotated = "rotated" // 已换取过新令牌，再次使用视为令牌泄露
+)
+
+var (
+	// ErrRefreshTokenNotFound 刷新令牌未登记、已吊销或已过期
+	ErrRefreshTokenNotFound = errors.New("refresh token not found")
+	// ErrRefreshTokenReused 已轮换的刷新令牌被再次使用，该用户的全部刷新令牌已被吊销
+	ErrRefreshTokenReused = errors.New("refresh token reused")
+)
+
+// RefreshTokenStore 刷新令牌登记存储
+//
+// 按JTI登记签发的刷新令牌，每次刷新时轮换：旧令牌标记为已轮换并登记新令牌。
+// 已轮换的令牌再次出现说明令牌可能被窃取，此时吊销该用户的全部刷新令牌，
+// 合法用户和攻击者都需要重新登录
+//
+// 使用示例：
+//
+//	store := cache.NewRedisRefreshTokenStore(cache.RedisClient)
+//	err := store.Issue(ctx, userID, claims.ID, claims.ExpiresAt.Time)
+//	err = store.Rotate(ctx, userID, oldClaims.ID, newClaims.ID, newClaims.ExpiresAt.Time)
+//	err = store.Revoke(ctx, userID, claims.ID)    // 登出
+//	err = store.RevokeAllForUser(ctx, userID)     // 修改或重置密码
+type RefreshTokenStore interface {
+	// Issue 登记新签发的刷新令牌
+	Issue(ctx context.Context, userID uint64, jti string, expiresAt time.Time) error
+	// Rotate 使用旧令牌换取新令牌，旧令牌不可用时返回 ErrRefreshTokenNotFound 或 ErrRefreshTokenReused
+	Rotate(ctx context.Context, userID uint64, oldJTI, newJTI string, expiresAt time.Time) error
+	// Active 判断刷新令牌是否仍可用于换取新令牌
+	Active(ctx context.Context, userID uint64, jti string) (bool, error)
+	// Revoke 吊销单个刷新令牌
+	Revoke(ctx context.Context, userID uint64, jti string) error
+	// RevokeAllForUser 吊销用户的全部刷新令牌
+	RevokeAllForUser(ctx context.Context, userID uint64) error
+}
+
+// memoryRefreshToken 进程内登记的刷新令牌
+type memoryRefreshToken struct {
+	userID    uint64
+	state     string
+	expiresAt time.Time
+}
+
+// MemoryRefreshTokenStore 进程内刷新令牌登记存储，用于单实例部署和测试
+type MemoryRefreshTokenStore struct {
+	mu     sync.Mutex
+	now    func() time.Time
+	tokens map[string]*memoryRefreshToken
+}
+
+// NewMemoryRefreshTokenStore 创建进程内刷新令牌登记存储
+func NewMemoryRefreshTokenStore() *MemoryRefreshTokenStore {
+	return &MemoryRefreshTokenStore{
+		now:    time.Now,
+		tokens: make(map[string]*memoryRefreshToken),
+	}
+}
+
+// Issue 登记新签发的刷新令牌，写入时顺带清理已过期的令牌
+func (s *MemoryRefreshTokenStore) Issue(_ context.Context, userID uint64, jti string, expiresAt time.Time) error {
+	if jti == "" {
+		return fmt.Errorf("刷新令牌JTI不能为空")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneLocked()
+	s.tokens[jti] = &memoryRefreshToken{userID: userID, state: refreshTokenActive, expiresAt: expiresAt}
+	return nil
+}
+
+// Rotate 使用旧令牌换取新令牌
+func (s *MemoryRefreshTokenStore) Rotate(_ context.Context, userID uint64, oldJTI, newJTI string, expiresAt time.Time) error {
+	if oldJTI == "" || newJTI == "" {
+		return fmt.Errorf("刷新令牌JTI不能为空")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, ok := s.tokens[oldJTI]
+	if !ok || old.userID != userID || !old.expiresAt.After(s.now()) {
+		return ErrRefreshTokenNotFound
+	}
+	if old.state == refreshTokenRotated {
+		s.revokeAllLocked(userID)
+		return ErrRefreshTokenReused
+	}
+
+	old.state = refreshTokenRotated
+	s.tokens[newJTI] = &memoryRefreshToken{userID: userID, state: refreshTokenActive, expiresAt: expiresAt}
+	return nil
+}
+
+// Active 判断刷新令牌是否仍可用于换取新令牌
+func (s *MemoryRefreshTokenStore) Active(_ context.Context, userID uint64, jti string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	token, ok := s.tokens[jti]
+	return ok && token.userID == userID && token.state == refreshTokenActive && token.expiresAt.After(s.now()), nil
+}
+
+// Revoke 吊销单个刷新令牌
+func (s *MemoryRefreshTokenStore) Revoke(_ context.Context, userID uint64, jti string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if token, ok := s.tokens[jti]; ok && token.userID == userID {
+		delete(s.tokens, jti)
+	}
+	return nil
+}
+
+// RevokeAllForUser 吊销用户的全部刷新令牌
+func (s *MemoryRefreshTokenStore) RevokeAllForUser(_ context.Context, userID uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.revokeAllLocked(userID)
+	return nil
+}
+
+// revokeAllLocked 删除用户的全部令牌，调用方需持有锁
+func (s *MemoryRefreshTokenStore) revokeAllLocked(userID uint64) {
+	for jti, token := range s.tokens {
+		if token.userID == userID {
+			delete(s.tokens, jti)
+		}
+	}
+}
+
+// PruneExpired 删除已过期的令牌，返回删除的数量，由定期维护任务调用
+func (s *MemoryRefreshTokenStore) PruneExpired() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pruneLocked()
+}
+
+// pruneLocked 删除已过期的令牌并返回删除的数量，调用方需持有锁
+func (s *MemoryRefreshTokenStore) pruneLocked() int {
+	now := s.now()
+	removed := 0
+	for jti, token := range s.tokens {
+		if !token.expiresAt.After(now) {
+			delete(s.tokens, jti)
+			removed++
+		}
+	}
+	return removed
+}
+
+// rotateRefreshTokenScript 原子地检查旧令牌状态并登记新令牌
+//
+// KEYS: 旧令牌键、新令牌键、用户令牌集合键
+// ARGV: 用户ID、新令牌有效期(毫秒)、新令牌JTI
+// 返回: 1 轮换成功，0 旧令牌不存在，-1 旧令牌已轮换
+var rotateRefreshTokenScript = redis.NewScript(`
+local current = redis.call('GET', KEYS[1])
+if not current then
+	return 0
+end
+if current == ARGV[1] .. ':rotated' then
+	return -1
+end
+if current ~= ARGV[1] .. ':active' then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[1] .. ':rotated', 'KEEPTTL')
+redis.call('SET', KEYS[2], ARGV[1] .. ':active', 'PX', ARGV[2])
+redis.call('SADD', KEYS[3], ARGV[3])
+redis.call('PEXPIRE', KEYS[3], ARGV[2])
+return 1
+`)
+
+// RedisRefreshTokenStore 基于Redis的刷新令牌登记存储，多实例部署时共享登记记录
+//
+// 每个令牌一个键(值为 用户ID:状态，过期时间与令牌一致)，另用集合记录用户的全部令牌用于批量吊销
+type RedisRefreshTokenStore struct {
+	client *redis.Client
+	now    func() time.Time
+}
+
+// NewRedisRefreshTokenStore 创建Redis刷新令牌登记存储
+func NewRedisRefreshTokenStore(client *redis.Client) *RedisRefreshTokenStore {
+	return &RedisRefreshTokenStore{
+		client: client,
+		now:    time.Now,
+	}
+}
+
+// Issue 登记新签发的刷新令牌
+func (s *RedisRefreshTokenStore) Issue(ctx context.Context, userID uint64, jti string, expiresAt time.Time) error {
+	if jti == "" {
+		return fmt.Errorf("刷新令牌JTI不能为空")
+	}
+	ttl := expiresAt.Sub(s.now())
+	if ttl <= 0 {
+		return fmt.Errorf("刷新令牌已过期")
+	}
+
+	userKey := Keys.UserRefreshTokens(formatUserID(userID))
+	pipe := s.client.TxPipeline()
+	pipe.Set(ctx, Keys.RefreshToken(jti), refreshTokenValue(userID, refreshTokenActive), ttl)
+	pipe.SAdd(ctx, userKey, jti)
+	pipe.Expire(ctx, userKey, ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("登记刷新令牌失败: %w", err)
+	}
+	return nil
+}
+
+// Rotate 使用旧令牌换取新令牌，检测到令牌重用时吊销用户的全部刷新令牌
+func (s *RedisRefreshTokenStore) Rotate(ctx context.Context, userID uint64, oldJTI, newJTI string, expiresAt time.Time) error {
+	if oldJTI == "" || newJTI == "" {
+		return fmt.Errorf("刷新令牌JTI不能为空")
+	}
+	ttl := expiresAt.Sub(s.now())
+	if ttl <= 0 {
+		return fmt.Errorf("刷新令牌已过期")
+	}
+
+	keys := []string{Keys.RefreshToken(oldJTI), Keys.RefreshToken(newJTI), Keys.UserRefreshTokens(formatUserID(userID))}
+	result, err := rotateRefreshTokenScript.Run(ctx, s.client, keys, formatUserID(userID), ttl.Milliseconds(), newJTI).Int()
+	if err != nil {
+		return fmt.Errorf("轮换刷新令牌失败: %w", err)
+	}
+
+	switch result {
+	case 1:
+		return nil
+	case -1:
+		if err := s.RevokeAllForUser(ctx, userID); err != nil {
+			return fmt.Errorf("%w: %v", ErrRefreshTokenReused, err)
+		}
+		return ErrRefreshTokenReused
+	default:
+		return ErrRefreshTokenNotFound
+	}
+}
+
+// Active 判断刷新令牌是否仍可用于换取新令牌
+func (s *RedisRefreshTokenStore) Active(ctx context.Context, userID uint64, jti string) (bool, error) {
+	value, err := s.client.Get(ctx, Keys.RefreshToken(jti)).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return false, nil
+		}
+		return false, fmt.Errorf("读取刷新令牌失败: %w", err)
+	}
+	return value == refreshTokenValue(userID, refreshTokenActive), nil
+}
+
+// Revoke 吊销单个刷新令牌，令牌不属于该用户时忽略
+func (s *RedisRefreshTokenStore) Revoke(ctx context.Context, userID uint64, jti string) error {
+	key := Keys.RefreshToken(jti)
+	value, err := s.client.Get(ctx, key).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil
+		}
+		return fmt.Errorf("读取刷新令牌失败: %w", err)
+	}
+	if !strings.HasPrefix(value, formatUserID(userID)+":") {
+		return nil
+	}
+
+	pipe := s.client.TxPipeline()
+	pipe.Del(ctx, key)
+	pipe.SRem(ctx, Keys.UserRefreshTokens(formatUserID(userID)), jti)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("吊销刷新令牌失败: %w", err)
+	}
+	return nil
+}
+
+// RevokeAllForUser 吊销用户的全部刷新令牌
+func (s *RedisRefreshTokenStore) RevokeAllForUser(ctx context.Context, userID uint64) error {
+	userKey := Keys.UserRefreshTokens(formatUserID(userID))
+	jtis, err := s.client.SMembers(ctx, userKey).Result()
+	if err != nil {
+		return fmt.Errorf("读取用户刷新令牌失败: %w", err)
+	}
+
+	keys := make([]string, 0, len(jtis)+1)
+	for _, jti := range jtis {
+		keys = append(keys, Keys.RefreshToken(jti))
+	}
+	keys = append(keys, userKey)
+	if err := s.client.Del(ctx, keys...).Err(); err != nil {
+		return fmt.Errorf("吊销用户刷新令牌失败: %w", err)
+	}
+	return nil
+}
+
+// refreshTokenValue 生成刷新令牌键的值
+func refreshTokenValue(userID uint64, state string) string {
+	return formatUserID(userID) + ":" + state
+}
+
+// formatUserID 格式化用户ID用于缓存键
+func formatUserID(userID uint64) string {
+	return strconv.FormatUint(userID, 10)
+}
+
+var (
+	defaultRefreshStoreMu sync.RWMutex
+	defaultRefreshStore   RefreshTokenStore
+)
+
+// SetDefaultRefreshTokenStore 设置全局刷新令牌登记存储，启动时调用
+func SetDefaultRefreshTokenStore(store RefreshTokenStore) {
+	defaultRefreshStoreMu.Lock()
+	defer defaultRefreshStoreMu.Unlock()
+	defaultRefreshStore = store
+}
+
+// DefaultRefreshTokenStore 返回全局刷新令牌登记存储，未设置时返回nil
+func DefaultRefreshTokenStore() RefreshTokenStore {
+	defaultRefreshStoreMu.RLock()
+	defer defaultRefreshStoreMu.RUnlock()
+	return defaultRefreshStore
+}
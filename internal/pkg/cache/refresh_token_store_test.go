@@ -0,0 +1,87 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryRefreshTokenStore_Rotate(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryRefreshTokenStore()
+	expiresAt := time.Now().Add(time.Hour)
+
+	require.NoError(t, store.Issue(ctx, 7, "a", expiresAt))
+	require.NoError(t, store.Rotate(ctx, 7, "a", "b", expiresAt))
+	require.NoError(t, store.Rotate(ctx, 7, "b", "c", expiresAt))
+
+	// 未登记或属于其他用户的令牌
+	assert.ErrorIs(t, store.Rotate(ctx, 7, "unknown", "d", expiresAt), ErrRefreshTokenNotFound)
+	assert.ErrorIs(t, store.Rotate(ctx, 8, "c", "d", expiresAt), ErrRefreshTokenNotFound)
+
+	// 重用已轮换的令牌会吊销该用户的全部令牌，包括最新的令牌
+	require.NoError(t, store.Issue(ctx, 8, "other", expiresAt))
+	assert.ErrorIs(t, store.Rotate(ctx, 7, "a", "d", expiresAt), ErrRefreshTokenReused)
+	assert.ErrorIs(t, store.Rotate(ctx, 7, "c", "d", expiresAt), ErrRefreshTokenNotFound)
+	assert.NoError(t, store.Rotate(ctx, 8, "other", "other2", expiresAt))
+}
+
+func TestMemoryRefreshTokenStore_Expired(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryRefreshTokenStore()
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	require.NoError(t, store.Issue(ctx, 7, "a", now.Add(time.Minute)))
+	now = now.Add(2 * time.Minute)
+	assert.ErrorIs(t, store.Rotate(ctx, 7, "a", "b", now.Add(time.Hour)), ErrRefreshTokenNotFound)
+
+	// 过期令牌在下次登记时被清理
+	require.NoError(t, store.Issue(ctx, 7, "b", now.Add(time.Hour)))
+	assert.NotContains(t, store.tokens, "a")
+}
+
+func TestMemoryRefreshTokenStore_Revoke(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryRefreshTokenStore()
+	expiresAt := time.Now().Add(time.Hour)
+
+	require.NoError(t, store.Issue(ctx, 7, "a", expiresAt))
+	require.NoError(t, store.Issue(ctx, 7, "b", expiresAt))
+	require.NoError(t, store.Issue(ctx, 8, "c", expiresAt))
+
+	// 不能吊销其他用户的令牌
+	require.NoError(t, store.Revoke(ctx, 8, "a"))
+	require.NoError(t, store.Revoke(ctx, 7, "a"))
+	assert.ErrorIs(t, store.Rotate(ctx, 7, "a", "x", expiresAt), ErrRefreshTokenNotFound)
+	assert.NoError(t, store.Rotate(ctx, 7, "b", "d", expiresAt))
+
+	require.NoError(t, store.RevokeAllForUser(ctx, 7))
+	assert.ErrorIs(t, store.Rotate(ctx, 7, "d", "e", expiresAt), ErrRefreshTokenNotFound)
+	assert.NoError(t, store.Rotate(ctx, 8, "c", "f", expiresAt))
+}
+
+func TestMemoryRefreshTokenStore_Active(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryRefreshTokenStore()
+	expiresAt := time.Now().Add(time.Hour)
+
+	require.NoError(t, store.Issue(ctx, 7, "a", expiresAt))
+	active, err := store.Active(ctx, 7, "a")
+	require.NoError(t, err)
+	assert.True(t, active)
+
+	// 其他用户、已轮换和未登记的令牌
+	active, _ = store.Active(ctx, 8, "a")
+	assert.False(t, active)
+	require.NoError(t, store.Rotate(ctx, 7, "a", "b", expiresAt))
+	active, _ = store.Active(ctx, 7, "a")
+	assert.False(t, active)
+	active, _ = store.Active(ctx, 7, "b")
+	assert.True(t, active)
+	active, _ = store.Active(ctx, 7, "unknown")
+	assert.False(t, active)
+}
@@ -0,0 +1,217 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// DefaultSearchHistorySize 每个用户保留的搜索历史条数
+const DefaultSearchHistorySize = 20
+
+// SearchHistoryEntry 搜索历史记录
+type SearchHistoryEntry struct {
+	Query      string    `json:"query"`       // 搜索关键词
+	SearchedAt time.Time `json:"searched_at"` // 最近一次搜索时间
+}
+
+// SearchHistoryStore 用户搜索历史存储
+//
+// 同一关键词只保留一条，重复搜索时更新搜索时间；每个用户最多保留 DefaultSearchHistorySize 条，
+// 超过 search_history TTL 未再搜索时整个历史过期
+//
+// 使用示例：
+//
+//	store := cache.NewRedisSearchHistoryStore(cache.RedisClient, ttl)
+//	err := store.Add(ctx, userID, "季度报告", time.Now())
+//	entries, err := store.List(ctx, userID)
+//	err = store.Clear(ctx, userID)
+type SearchHistoryStore interface {
+	// Add 记录一次搜索
+	Add(ctx context.Context, userID uint64, query string, at time.Time) error
+	// List 返回用户的搜索历史，按搜索时间倒序
+	List(ctx context.Context, userID uint64) ([]SearchHistoryEntry, error)
+	// Clear 清空用户的搜索历史
+	Clear(ctx context.Context, userID uint64) error
+}
+
+// MemorySearchHistoryStore 进程内搜索历史存储，用于单实例部署和测试
+type MemorySearchHistoryStore struct {
+	mu        sync.Mutex
+	now       func() time.Time
+	ttl       time.Duration
+	histories map[uint64]*memorySearchHistory
+}
+
+// memorySearchHistory 单个用户的搜索历史
+type memorySearchHistory struct {
+	entries   map[string]time.Time
+	expiresAt time.Time
+}
+
+// NewMemorySearchHistoryStore 创建进程内搜索历史存储，ttl为0时历史不过期
+func NewMemorySearchHistoryStore(ttl time.Duration) *MemorySearchHistoryStore {
+	return &MemorySearchHistoryStore{
+		now:       time.Now,
+		ttl:       ttl,
+		histories: make(map[uint64]*memorySearchHistory),
+	}
+}
+
+// Add 记录一次搜索
+func (s *MemorySearchHistoryStore) Add(_ context.Context, userID uint64, query string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	history := s.active(userID)
+	if history == nil {
+		history = &memorySearchHistory{entries: make(map[string]time.Time)}
+		s.histories[userID] = history
+	}
+	history.entries[query] = at
+	if s.ttl > 0 {
+		history.expiresAt = s.now().Add(s.ttl)
+	}
+
+	// 超过上限时删除最早的记录
+	for len(history.entries) > DefaultSearchHistorySize {
+		oldest := ""
+		for q, searchedAt := range history.entries {
+			if oldest == "" || searchedAt.Before(history.entries[oldest]) {
+				oldest = q
+			}
+		}
+		delete(history.entries, oldest)
+	}
+	return nil
+}
+
+// List 返回用户的搜索历史
+func (s *MemorySearchHistoryStore) List(_ context.Context, userID uint64) ([]SearchHistoryEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	history := s.active(userID)
+	if history == nil {
+		return []SearchHistoryEntry{}, nil
+	}
+	entries := make([]SearchHistoryEntry, 0, len(history.entries))
+	for query, searchedAt := range history.entries {
+		entries = append(entries, SearchHistoryEntry{Query: query, SearchedAt: searchedAt})
+	}
+	sortSearchHistory(entries)
+	return entries, nil
+}
+
+// Clear 清空用户的搜索历史
+func (s *MemorySearchHistoryStore) Clear(_ context.Context, userID uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.histories, userID)
+	return nil
+}
+
+// active 返回未过期的用户搜索历史，已过期的顺带删除，调用方需持有锁
+func (s *MemorySearchHistoryStore) active(userID uint64) *memorySearchHistory {
+	history, ok := s.histories[userID]
+	if !ok {
+		return nil
+	}
+	if !history.expiresAt.IsZero() && !history.expiresAt.After(s.now()) {
+		delete(s.histories, userID)
+		return nil
+	}
+	return history
+}
+
+// sortSearchHistory 按搜索时间倒序排列搜索历史
+func sortSearchHistory(entries []SearchHistoryEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].SearchedAt.Equal(entries[j].SearchedAt) {
+			return entries[i].SearchedAt.After(entries[j].SearchedAt)
+		}
+		return entries[i].Query < entries[j].Query
+	})
+}
+
+// RedisSearchHistoryStore 基于Redis的搜索历史存储，多实例部署时共享搜索历史
+//
+// 每个用户一个有序集合：成员为关键词，分值为搜索时间(毫秒)
+type RedisSearchHistoryStore struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+// NewRedisSearchHistoryStore 创建Redis搜索历史存储，ttl为0时历史不过期
+func NewRedisSearchHistoryStore(client *redis.Client, ttl time.Duration) *RedisSearchHistoryStore {
+	return &RedisSearchHistoryStore{
+		client: client,
+		ttl:    ttl,
+	}
+}
+
+// Add 记录一次搜索
+func (s *RedisSearchHistoryStore) Add(ctx context.Context, userID uint64, query string, at time.Time) error {
+	key := Keys.SearchHistory(strconv.FormatUint(userID, 10))
+	pipe := s.client.TxPipeline()
+	pipe.ZAdd(ctx, key, &redis.Z{Score: float64(at.UnixMilli()), Member: query})
+	// 只保留分值最大(最近)的若干条
+	pipe.ZRemRangeByRank(ctx, key, 0, -DefaultSearchHistorySize-1)
+	if s.ttl > 0 {
+		pipe.Expire(ctx, key, s.ttl)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("记录搜索历史失败: %w", err)
+	}
+	return nil
+}
+
+// List 返回用户的搜索历史
+func (s *RedisSearchHistoryStore) List(ctx context.Context, userID uint64) ([]SearchHistoryEntry, error) {
+	key := Keys.SearchHistory(strconv.FormatUint(userID, 10))
+	members, err := s.client.ZRevRangeWithScores(ctx, key, 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("读取搜索历史失败: %w", err)
+	}
+	entries := make([]SearchHistoryEntry, 0, len(members))
+	for _, member := range members {
+		query, ok := member.Member.(string)
+		if !ok {
+			continue
+		}
+		entries = append(entries, SearchHistoryEntry{Query: query, SearchedAt: time.UnixMilli(int64(member.Score))})
+	}
+	return entries, nil
+}
+
+// Clear 清空用户的搜索历史
+func (s *RedisSearchHistoryStore) Clear(ctx context.Context, userID uint64) error {
+	if err := s.client.Del(ctx, Keys.SearchHistory(strconv.FormatUint(userID, 10))).Err(); err != nil {
+		return fmt.Errorf("清空搜索历史失败: %w", err)
+	}
+	return nil
+}
+
+var (
+	defaultSearchHistoryStoreMu sync.RWMutex
+	defaultSearchHistoryStore   SearchHistoryStore
+)
+
+// SetDefaultSearchHistoryStore 设置全局搜索历史存储，启动时调用
+func SetDefaultSearchHistoryStore(store SearchHistoryStore) {
+	defaultSearchHistoryStoreMu.Lock()
+	defer defaultSearchHistoryStoreMu.Unlock()
+	defaultSearchHistoryStore = store
+}
+
+// DefaultSearchHistoryStore 返回全局搜索历史存储，未设置时返回nil
+func DefaultSearchHistoryStore() SearchHistoryStore {
+	defaultSearchHistoryStoreMu.RLock()
+	defer defaultSearchHistoryStoreMu.RUnlock()
+	return defaultSearchHistoryStore
+}
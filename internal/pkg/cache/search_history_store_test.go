@@ -0,0 +1,55 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemorySearchHistoryStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemorySearchHistoryStore(time.Hour)
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	require.NoError(t, store.Add(ctx, 7, "报告", now))
+	require.NoError(t, store.Add(ctx, 7, "合同", now.Add(time.Second)))
+	// 重复搜索只更新时间
+	require.NoError(t, store.Add(ctx, 7, "报告", now.Add(2*time.Second)))
+
+	entries, err := store.List(ctx, 7)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "报告", entries[0].Query)
+	assert.Equal(t, "合同", entries[1].Query)
+
+	// 其他用户的历史互不影响
+	entries, err = store.List(ctx, 8)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	// 超过上限时删除最早的记录
+	for i := 0; i < DefaultSearchHistorySize; i++ {
+		require.NoError(t, store.Add(ctx, 7, fmt.Sprintf("q%d", i), now.Add(time.Duration(10+i)*time.Second)))
+	}
+	entries, err = store.List(ctx, 7)
+	require.NoError(t, err)
+	assert.Len(t, entries, DefaultSearchHistorySize)
+	assert.Equal(t, fmt.Sprintf("q%d", DefaultSearchHistorySize-1), entries[0].Query)
+
+	require.NoError(t, store.Clear(ctx, 7))
+	entries, err = store.List(ctx, 7)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	// 超过TTL未再搜索时历史过期
+	require.NoError(t, store.Add(ctx, 9, "发票", now))
+	now = now.Add(2 * time.Hour)
+	entries, err = store.List(ctx, 9)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
@@ -0,0 +1,269 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ErrSessionNotFound 登录会话不存在、已过期或不属于该用户
+var ErrSessionNotFound = errors.New("session not found")
+
+// Session 登录会话
+//
+// 一次登录对应一个会话，会话跟随刷新令牌轮换：每次刷新时更新当前刷新令牌、IP和最后活跃时间
+type Session struct {
+	ID         string    `json:"id"`          // 会话ID
+	UserID     uint64    `json:"user_id"`     // 用户ID
+	RefreshJTI string    `json:"refresh_jti"` // 当前刷新令牌的JTI
+	Device     string    `json:"device"`      // 登录设备(User-Agent)
+	IP         string    `json:"ip"`          // 最近一次使用的IP
+	CreatedAt  time.Time `json:"created_at"`  // 登录时间
+	LastSeen   time.Time `json:"last_seen"`   // 最后活跃时间(登录或刷新令牌)
+	ExpiresAt  time.Time `json:"expires_at"`  // 当前刷新令牌的过期时间，之后会话自动删除
+}
+
+// SessionStore 登录会话存储
+//
+// 使用示例：
+//
+//	store := cache.NewRedisSessionStore(cache.RedisClient)
+//	err := store.Save(ctx, &cache.Session{ID: id, UserID: userID, RefreshJTI: claims.ID, ExpiresAt: claims.ExpiresAt.Time})
+//	session, err := cache.FindSessionByRefreshToken(ctx, store, userID, claims.ID)
+//	sessions, err := store.List(ctx, userID)
+//	session, err = store.Delete(ctx, userID, sessionID)
+type SessionStore interface {
+	// Save 创建或更新会话
+	Save(ctx context.Context, session *Session) error
+	// List 返回用户未过期的会话，按最后活跃时间倒序
+	List(ctx context.Context, userID uint64) ([]*Session, error)
+	// Delete 删除并返回会话，会话不存在或不属于该用户时返回 ErrSessionNotFound
+	Delete(ctx context.Context, userID uint64, sessionID string) (*Session, error)
+}
+
+// FindSessionByRefreshToken 查找当前刷新令牌为jti的会话，不存在时返回 ErrSessionNotFound
+func FindSessionByRefreshToken(ctx context.Context, store SessionStore, userID uint64, jti string) (*Session, error) {
+	sessions, err := store.List(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, session := range sessions {
+		if session.RefreshJTI == jti {
+			return session, nil
+		}
+	}
+	return nil, ErrSessionNotFound
+}
+
+// sortSessions 按最后活跃时间倒序排列会话
+func sortSessions(sessions []*Session) {
+	sort.Slice(sessions, func(i, j int) bool {
+		if !sessions[i].LastSeen.Equal(sessions[j].LastSeen) {
+			return sessions[i].LastSeen.After(sessions[j].LastSeen)
+		}
+		return sessions[i].ID < sessions[j].ID
+	})
+}
+
+// MemorySessionStore 进程内登录会话存储，用于单实例部署和测试
+type MemorySessionStore struct {
+	mu       sync.Mutex
+	now      func() time.Time
+	sessions map[string]Session
+}
+
+// NewMemorySessionStore 创建进程内登录会话存储
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{
+		now:      time.Now,
+		sessions: make(map[string]Session),
+	}
+}
+
+// Save 创建或更新会话，写入时顺带清理已过期的会话
+func (s *MemorySessionStore) Save(_ context.Context, session *Session) error {
+	if session.ID == "" {
+		return fmt.Errorf("会话ID不能为空")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	for id, existing := range s.sessions {
+		if !existing.ExpiresAt.After(now) {
+			delete(s.sessions, id)
+		}
+	}
+	s.sessions[session.ID] = *session
+	return nil
+}
+
+// List 返回用户未过期的会话
+func (s *MemorySessionStore) List(_ context.Context, userID uint64) ([]*Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	var sessions []*Session
+	for _, session := range s.sessions {
+		if session.UserID == userID && session.ExpiresAt.After(now) {
+			session := session
+			sessions = append(sessions, &session)
+		}
+	}
+	sortSessions(sessions)
+	return sessions, nil
+}
+
+// Delete 删除并返回会话
+func (s *MemorySessionStore) Delete(_ context.Context, userID uint64, sessionID string) (*Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[sessionID]
+	if !ok || session.UserID != userID {
+		return nil, ErrSessionNotFound
+	}
+	delete(s.sessions, sessionID)
+	if !session.ExpiresAt.After(s.now()) {
+		return nil, ErrSessionNotFound
+	}
+	return &session, nil
+}
+
+// RedisSessionStore 基于Redis的登录会话存储，多实例部署时共享会话
+//
+// 每个会话一个键(值为JSON，过期时间与当前刷新令牌一致)，另用集合记录用户的全部会话ID
+type RedisSessionStore struct {
+	client *redis.Client
+	now    func() time.Time
+}
+
+// NewRedisSessionStore 创建Redis登录会话存储
+func NewRedisSessionStore(client *redis.Client) *RedisSessionStore {
+	return &RedisSessionStore{
+		client: client,
+		now:    time.Now,
+	}
+}
+
+// Save 创建或更新会话
+//
+// 会话集合的过期时间取集合当前过期时间和本会话过期时间中较晚的一个，避免提前删除其他会话的索引
+func (s *RedisSessionStore) Save(ctx context.Context, session *Session) error {
+	if session.ID == "" {
+		return fmt.Errorf("会话ID不能为空")
+	}
+	ttl := session.ExpiresAt.Sub(s.now())
+	if ttl <= 0 {
+		return fmt.Errorf("会话已过期")
+	}
+	data, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("序列化会话失败: %w", err)
+	}
+
+	userKey := Keys.UserSessions(formatUserID(session.UserID))
+	setTTL := ttl
+	if current, err := s.client.PTTL(ctx, userKey).Result(); err == nil && current > setTTL {
+		setTTL = current
+	}
+	pipe := s.client.TxPipeline()
+	pipe.Set(ctx, Keys.UserSession(session.ID), data, ttl)
+	pipe.SAdd(ctx, userKey, session.ID)
+	pipe.PExpire(ctx, userKey, setTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("保存会话失败: %w", err)
+	}
+	return nil
+}
+
+// List 返回用户未过期的会话，顺带从会话集合中移除已过期的会话ID
+func (s *RedisSessionStore) List(ctx context.Context, userID uint64) ([]*Session, error) {
+	userKey := Keys.UserSessions(formatUserID(userID))
+	ids, err := s.client.SMembers(ctx, userKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("读取用户会话失败: %w", err)
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = Keys.UserSession(id)
+	}
+	values, err := s.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("读取用户会话失败: %w", err)
+	}
+
+	var sessions []*Session
+	var stale []interface{}
+	for i, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			stale = append(stale, ids[i])
+			continue
+		}
+		var session Session
+		if err := json.Unmarshal([]byte(data), &session); err != nil || session.UserID != userID {
+			stale = append(stale, ids[i])
+			continue
+		}
+		sessions = append(sessions, &session)
+	}
+	if len(stale) > 0 {
+		// 清理失败不影响本次结果，下次读取时重试
+		_ = s.client.SRem(ctx, userKey, stale...).Err()
+	}
+	sortSessions(sessions)
+	return sessions, nil
+}
+
+// Delete 删除并返回会话
+func (s *RedisSessionStore) Delete(ctx context.Context, userID uint64, sessionID string) (*Session, error) {
+	key := Keys.UserSession(sessionID)
+	data, err := s.client.Get(ctx, key).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, ErrSessionNotFound
+		}
+		return nil, fmt.Errorf("读取会话失败: %w", err)
+	}
+	var session Session
+	if err := json.Unmarshal([]byte(data), &session); err != nil || session.UserID != userID {
+		return nil, ErrSessionNotFound
+	}
+
+	pipe := s.client.TxPipeline()
+	pipe.Del(ctx, key)
+	pipe.SRem(ctx, Keys.UserSessions(formatUserID(userID)), sessionID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("删除会话失败: %w", err)
+	}
+	return &session, nil
+}
+
+var (
+	defaultSessionStoreMu sync.RWMutex
+	defaultSessionStore   SessionStore
+)
+
+// SetDefaultSessionStore 设置全局登录会话存储，启动时调用
+func SetDefaultSessionStore(store SessionStore) {
+	defaultSessionStoreMu.Lock()
+	defer defaultSessionStoreMu.Unlock()
+	defaultSessionStore = store
+}
+
+// DefaultSessionStore 返回全局登录会话存储，未设置时返回nil
+func DefaultSessionStore() SessionStore {
+	defaultSessionStoreMu.RLock()
+	defer defaultSessionStoreMu.RUnlock()
+	return defaultSessionStore
+}
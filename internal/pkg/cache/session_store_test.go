@@ -0,0 +1,56 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemorySessionStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemorySessionStore()
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	require.NoError(t, store.Save(ctx, &Session{ID: "a", UserID: 7, RefreshJTI: "jti-a", LastSeen: now, ExpiresAt: now.Add(time.Hour)}))
+	require.NoError(t, store.Save(ctx, &Session{ID: "b", UserID: 7, RefreshJTI: "jti-b", LastSeen: now.Add(time.Minute), ExpiresAt: now.Add(time.Minute * 2)}))
+	require.NoError(t, store.Save(ctx, &Session{ID: "c", UserID: 8, RefreshJTI: "jti-c", LastSeen: now, ExpiresAt: now.Add(time.Hour)}))
+	assert.Error(t, store.Save(ctx, &Session{UserID: 7}))
+
+	sessions, err := store.List(ctx, 7)
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	assert.Equal(t, "b", sessions[0].ID, "最后活跃的会话排在最前")
+
+	session, err := FindSessionByRefreshToken(ctx, store, 7, "jti-a")
+	require.NoError(t, err)
+	assert.Equal(t, "a", session.ID)
+	_, err = FindSessionByRefreshToken(ctx, store, 7, "jti-c")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+
+	// 返回的会话是副本，修改后需要保存
+	session.RefreshJTI = "jti-a2"
+	_, err = FindSessionByRefreshToken(ctx, store, 7, "jti-a2")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+
+	// 过期的会话不再返回
+	now = now.Add(5 * time.Minute)
+	sessions, err = store.List(ctx, 7)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	_, err = store.Delete(ctx, 7, "b")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+
+	// 不能删除其他用户的会话
+	_, err = store.Delete(ctx, 8, "a")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	deleted, err := store.Delete(ctx, 7, "a")
+	require.NoError(t, err)
+	assert.Equal(t, "jti-a", deleted.RefreshJTI)
+	sessions, err = store.List(ctx, 7)
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+}
@@ -0,0 +1,161 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ErrSSOStateNotFound 单点登录请求不存在、已使用或已过期
+var ErrSSOStateNotFound = errors.New("sso state not found")
+
+// SSOState 进行中的单点登录请求
+//
+// 跳转到身份提供方前保存，回调时按state取出，用于校验ID令牌的nonce和换取令牌时的PKCE校验码
+type SSOState struct {
+	State      string    `json:"state"`       // 随授权请求发送的state
+	ProviderID uint      `json:"provider_id"` // 身份提供方ID
+	Nonce      string    `json:"nonce"`       // 写入ID令牌的nonce
+	Verifier   string    `json:"verifier"`    // PKCE校验码
+	RememberMe bool      `json:"remember_me"` // 登录请求中的记住我选项
+	ExpiresAt  time.Time `json:"expires_at"`  // 过期时间
+}
+
+// SSOStateStore 单点登录请求存储
+//
+// 使用示例：
+//
+//	store := cache.NewRedisSSOStateStore(cache.RedisClient)
+//	err := store.Save(ctx, &cache.SSOState{State: state, ProviderID: id, ExpiresAt: time.Now().Add(10 * time.Minute)})
+//	pending, err := store.Take(ctx, state)
+type SSOStateStore interface {
+	// Save 保存请求，过期时间到达后自动删除
+	Save(ctx context.Context, state *SSOState) error
+	// Take 取出并删除请求，每个state只能使用一次；不存在或已过期时返回 ErrSSOStateNotFound
+	Take(ctx context.Context, state string) (*SSOState, error)
+}
+
+// MemorySSOStateStore 进程内单点登录请求存储，用于单实例部署和测试
+type MemorySSOStateStore struct {
+	mu     sync.Mutex
+	now    func() time.Time
+	states map[string]SSOState
+}
+
+// NewMemorySSOStateStore 创建进程内单点登录请求存储
+func NewMemorySSOStateStore() *MemorySSOStateStore {
+	return &MemorySSOStateStore{
+		now:    time.Now,
+		states: make(map[string]SSOState),
+	}
+}
+
+// Save 保存请求，写入时顺带清理已过期的请求
+func (s *MemorySSOStateStore) Save(_ context.Context, state *SSOState) error {
+	if state.State == "" {
+		return fmt.Errorf("state不能为空")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	for key, existing := range s.states {
+		if !existing.ExpiresAt.After(now) {
+			delete(s.states, key)
+		}
+	}
+	s.states[state.State] = *state
+	return nil
+}
+
+// Take 取出并删除请求
+func (s *MemorySSOStateStore) Take(_ context.Context, key string) (*SSOState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, ok := s.states[key]
+	if !ok {
+		return nil, ErrSSOStateNotFound
+	}
+	delete(s.states, key)
+	if !state.ExpiresAt.After(s.now()) {
+		return nil, ErrSSOStateNotFound
+	}
+	return &state, nil
+}
+
+// RedisSSOStateStore 基于Redis的单点登录请求存储，多实例部署时共享请求
+type RedisSSOStateStore struct {
+	client *redis.Client
+	now    func() time.Time
+}
+
+// NewRedisSSOStateStore 创建Redis单点登录请求存储
+func NewRedisSSOStateStore(client *redis.Client) *RedisSSOStateStore {
+	return &RedisSSOStateStore{
+		client: client,
+		now:    time.Now,
+	}
+}
+
+// Save 保存请求
+func (s *RedisSSOStateStore) Save(ctx context.Context, state *SSOState) error {
+	if state.State == "" {
+		return fmt.Errorf("state不能为空")
+	}
+	ttl := state.ExpiresAt.Sub(s.now())
+	if ttl <= 0 {
+		return fmt.Errorf("单点登录请求已过期")
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("序列化单点登录请求失败: %w", err)
+	}
+	if err := s.client.Set(ctx, Keys.SSOState(state.State), data, ttl).Err(); err != nil {
+		return fmt.Errorf("保存单点登录请求失败: %w", err)
+	}
+	return nil
+}
+
+// Take 在同一事务中读取并删除请求，并发回调只有一个能取到
+func (s *RedisSSOStateStore) Take(ctx context.Context, key string) (*SSOState, error) {
+	redisKey := Keys.SSOState(key)
+	pipe := s.client.TxPipeline()
+	get := pipe.Get(ctx, redisKey)
+	pipe.Del(ctx, redisKey)
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("读取单点登录请求失败: %w", err)
+	}
+	data, err := get.Bytes()
+	if err != nil {
+		return nil, ErrSSOStateNotFound
+	}
+	var state SSOState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, ErrSSOStateNotFound
+	}
+	return &state, nil
+}
+
+var (
+	defaultSSOStateStoreMu sync.RWMutex
+	defaultSSOStateStore   SSOStateStore
+)
+
+// SetDefaultSSOStateStore 设置全局单点登录请求存储，启动时调用
+func SetDefaultSSOStateStore(store SSOStateStore) {
+	defaultSSOStateStoreMu.Lock()
+	defer defaultSSOStateStoreMu.Unlock()
+	defaultSSOStateStore = store
+}
+
+// DefaultSSOStateStore 返回全局单点登录请求存储，未设置时返回nil
+func DefaultSSOStateStore() SSOStateStore {
+	defaultSSOStateStoreMu.RLock()
+	defer defaultSSOStateStoreMu.RUnlock()
+	return defaultSSOStateStore
+}
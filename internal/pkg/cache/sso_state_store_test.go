@@ -0,0 +1,36 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemorySSOStateStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemorySSOStateStore()
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	require.NoError(t, store.Save(ctx, &SSOState{State: "a", ProviderID: 3, Nonce: "n", Verifier: "v", ExpiresAt: now.Add(10 * time.Minute)}))
+	assert.Error(t, store.Save(ctx, &SSOState{ProviderID: 3}))
+
+	state, err := store.Take(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, uint(3), state.ProviderID)
+	assert.Equal(t, "n", state.Nonce)
+	assert.Equal(t, "v", state.Verifier)
+
+	// 每个state只能使用一次
+	_, err = store.Take(ctx, "a")
+	assert.ErrorIs(t, err, ErrSSOStateNotFound)
+
+	// 过期的请求不可使用
+	require.NoError(t, store.Save(ctx, &SSOState{State: "b", ProviderID: 3, ExpiresAt: now.Add(time.Minute)}))
+	now = now.Add(2 * time.Minute)
+	_, err = store.Take(ctx, "b")
+	assert.ErrorIs(t, err, ErrSSOStateNotFound)
+}
@@ -0,0 +1,163 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// TokenStore 令牌吊销存储
+//
+// JWT令牌无状态，无法逐个作废；吊销按用户记录一个时间点，
+// 签发时间不晚于该时间点的令牌(访问令牌和刷新令牌)都视为失效，
+// 用户重新登录后签发的令牌不受影响
+//
+// 使用示例：
+//
+//	store := cache.NewRedisTokenStore(cache.RedisClient, refreshExpiry)
+//	err := store.RevokeUserTokens(ctx, userID, time.Now())
+//	revoked, err := cache.IsTokenRevoked(ctx, store, claims.UserID, claims.IssuedAt.Time)
+type TokenStore interface {
+	// RevokeUserTokens 吊销用户在before及之前签发的全部令牌
+	RevokeUserTokens(ctx context.Context, userID uint64, before time.Time) error
+	// RevokedBefore 返回用户的令牌吊销时间，未吊销时返回零值
+	RevokedBefore(ctx context.Context, userID uint64) (time.Time, error)
+}
+
+// IsTokenRevoked 检查指定签发时间的令牌是否已被吊销
+//
+// JWT签发时间精确到秒，吊销时间同样按秒比较，同一秒内签发的令牌视为已吊销
+func IsTokenRevoked(ctx context.Context, store TokenStore, userID uint64, issuedAt time.Time) (bool, error) {
+	if store == nil {
+		return false, nil
+	}
+	before, err := store.RevokedBefore(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	if before.IsZero() {
+		return false, nil
+	}
+	return !issuedAt.Truncate(time.Second).After(before.Truncate(time.Second)), nil
+}
+
+// MemoryTokenStore 进程内令牌吊销存储，用于单实例部署和测试
+type MemoryTokenStore struct {
+	mu      sync.RWMutex
+	ttl     time.Duration
+	now     func() time.Time
+	revoked map[uint64]time.Time
+}
+
+// NewMemoryTokenStore 创建进程内令牌吊销存储
+//
+// ttl为令牌的最长有效期，超过ttl的吊销记录不再有意义，写入时顺带清理
+func NewMemoryTokenStore(ttl time.Duration) *MemoryTokenStore {
+	return &MemoryTokenStore{
+		ttl:     ttl,
+		now:     time.Now,
+		revoked: make(map[uint64]time.Time),
+	}
+}
+
+// RevokeUserTokens 记录用户的令牌吊销时间，吊销时间只前进不后退
+func (s *MemoryTokenStore) RevokeUserTokens(_ context.Context, userID uint64, before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pruneLocked()
+	if current, ok := s.revoked[userID]; !ok || before.After(current) {
+		s.revoked[userID] = before
+	}
+	return nil
+}
+
+// PruneExpired 删除超过令牌最长有效期的吊销记录，返回删除的数量，由定期维护任务调用
+func (s *MemoryTokenStore) PruneExpired() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pruneLocked()
+}
+
+// pruneLocked 删除超过ttl的吊销记录并返回删除的数量，调用方需持有锁
+func (s *MemoryTokenStore) pruneLocked() int {
+	if s.ttl <= 0 {
+		return 0
+	}
+	expired := s.now().Add(-s.ttl)
+	removed := 0
+	for id, at := range s.revoked {
+		if at.Before(expired) {
+			delete(s.revoked, id)
+			removed++
+		}
+	}
+	return removed
+}
+
+// RevokedBefore 返回用户的令牌吊销时间
+func (s *MemoryTokenStore) RevokedBefore(_ context.Context, userID uint64) (time.Time, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.revoked[userID], nil
+}
+
+// RedisTokenStore 基于Redis的令牌吊销存储，多实例部署时共享吊销记录
+type RedisTokenStore struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+// NewRedisTokenStore 创建Redis令牌吊销存储，ttl为令牌的最长有效期
+func NewRedisTokenStore(client *redis.Client, ttl time.Duration) *RedisTokenStore {
+	return &RedisTokenStore{
+		client: client,
+		ttl:    ttl,
+	}
+}
+
+// RevokeUserTokens 记录用户的令牌吊销时间，记录在令牌最长有效期后自动过期
+func (s *RedisTokenStore) RevokeUserTokens(ctx context.Context, userID uint64, before time.Time) error {
+	key := Keys.UserRevocation(strconv.FormatUint(userID, 10))
+	if err := s.client.Set(ctx, key, before.Unix(), s.ttl).Err(); err != nil {
+		return fmt.Errorf("保存令牌吊销记录失败: %w", err)
+	}
+	return nil
+}
+
+// RevokedBefore 返回用户的令牌吊销时间
+func (s *RedisTokenStore) RevokedBefore(ctx context.Context, userID uint64) (time.Time, error) {
+	key := Keys.UserRevocation(strconv.FormatUint(userID, 10))
+	unix, err := s.client.Get(ctx, key).Int64()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, fmt.Errorf("读取令牌吊销记录失败: %w", err)
+	}
+	return time.Unix(unix, 0), nil
+}
+
+var (
+	defaultTokenStoreMu sync.RWMutex
+	defaultTokenStore   TokenStore
+)
+
+// SetDefaultTokenStore 设置全局令牌吊销存储，启动时调用
+func SetDefaultTokenStore(store TokenStore) {
+	defaultTokenStoreMu.Lock()
+	defer defaultTokenStoreMu.Unlock()
+	defaultTokenStore = store
+}
+
+// DefaultTokenStore 返回全局令牌吊销存储，未设置时返回nil
+func DefaultTokenStore() TokenStore {
+	defaultTokenStoreMu.RLock()
+	defer defaultTokenStoreMu.RUnlock()
+	return defaultTokenStore
+}
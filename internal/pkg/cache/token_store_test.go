@@ -0,0 +1,61 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsTokenRevoked(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryTokenStore(time.Hour)
+	revokedAt := time.Date(2024, 5, 1, 10, 0, 0, 500, time.UTC)
+	require.NoError(t, store.RevokeUserTokens(ctx, 7, revokedAt))
+
+	cases := []struct {
+		name     string
+		userID   uint64
+		issuedAt time.Time
+		revoked  bool
+	}{
+		{"issued before revocation", 7, revokedAt.Add(-time.Minute), true},
+		{"issued in the same second", 7, revokedAt.Truncate(time.Second), true},
+		{"issued after revocation", 7, revokedAt.Add(time.Second), false},
+		{"other user", 8, revokedAt.Add(-time.Minute), false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			revoked, err := IsTokenRevoked(ctx, store, tc.userID, tc.issuedAt)
+			require.NoError(t, err)
+			assert.Equal(t, tc.revoked, revoked)
+		})
+	}
+
+	revoked, err := IsTokenRevoked(ctx, nil, 7, revokedAt)
+	require.NoError(t, err)
+	assert.False(t, revoked)
+}
+
+func TestMemoryTokenStore_RevokeUserTokens(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	store := NewMemoryTokenStore(time.Hour)
+	store.now = func() time.Time { return now }
+
+	// 吊销时间只前进不后退
+	require.NoError(t, store.RevokeUserTokens(ctx, 7, now))
+	require.NoError(t, store.RevokeUserTokens(ctx, 7, now.Add(-time.Minute)))
+	before, err := store.RevokedBefore(ctx, 7)
+	require.NoError(t, err)
+	assert.Equal(t, now, before)
+
+	// 超过令牌有效期的记录在写入时清理
+	now = now.Add(2 * time.Hour)
+	require.NoError(t, store.RevokeUserTokens(ctx, 8, now))
+	before, err = store.RevokedBefore(ctx, 7)
+	require.NoError(t, err)
+	assert.True(t, before.IsZero())
+}
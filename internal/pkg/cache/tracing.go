@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"context"
+
+	"github.com/go-redis/redis/v8"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"cloudpan/internal/pkg/tracing"
+)
+
+// tracingSpanKey 上下文中保存Redis命令span的键，AfterProcess 只结束由钩子创建的span
+type tracingSpanKey struct{}
+
+// TracingHook Redis追踪钩子
+//
+// 为命令和管道创建客户端span，记录命令名(不含参数，避免记录缓存值)。
+// 与GORM插件相同，只在请求链路中创建span；CacheManager 需通过 WithContext 绑定请求上下文
+//
+// 使用示例：
+//
+//	client.AddHook(cache.TracingHook{})
+type TracingHook struct{}
+
+var _ redis.Hook = TracingHook{}
+
+// BeforeProcess 命令执行前创建span
+func (TracingHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return startRedisSpan(ctx, "redis."+cmd.Name(), attribute.String("db.operation.name", cmd.Name())), nil
+}
+
+// AfterProcess 命令执行后结束span，键不存在不视为失败
+func (TracingHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	endRedisSpan(ctx, cmd.Err())
+	return nil
+}
+
+// BeforeProcessPipeline 管道执行前创建span
+func (TracingHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return startRedisSpan(ctx, "redis.pipeline", attribute.Int("db.redis.pipeline_length", len(cmds))), nil
+}
+
+// AfterProcessPipeline 管道执行后结束span，任一命令失败时记录第一个错误
+func (TracingHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	var firstErr error
+	for _, cmd := range cmds {
+		if err := cmd.Err(); err != nil && err != redis.Nil {
+			firstErr = err
+			break
+		}
+	}
+	endRedisSpan(ctx, firstErr)
+	return nil
+}
+
+// startRedisSpan 在请求链路中创建Redis客户端span
+func startRedisSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) context.Context {
+	if !tracing.InTrace(ctx) {
+		return ctx
+	}
+	attrs = append(attrs, semconv.DBSystemRedis)
+	ctx, span := tracing.Tracer().Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	return context.WithValue(ctx, tracingSpanKey{}, span)
+}
+
+// endRedisSpan 结束钩子创建的span
+func endRedisSpan(ctx context.Context, err error) {
+	span, ok := ctx.Value(tracingSpanKey{}).(trace.Span)
+	if !ok {
+		return
+	}
+	if err != nil && err != redis.Nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
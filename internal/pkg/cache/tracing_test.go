@@ -0,0 +1,64 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"cloudpan/internal/pkg/tracing"
+)
+
+func TestTracingHook(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(previous)
+	hook := TracingHook{}
+
+	// 不在链路中的命令不创建span
+	background := redis.NewStringCmd(context.Background(), "get", "k")
+	ctx, err := hook.BeforeProcess(context.Background(), background)
+	require.NoError(t, err)
+	require.NoError(t, hook.AfterProcess(ctx, background))
+	assert.Empty(t, recorder.Ended())
+
+	requestCtx, request := tracing.Start(context.Background(), "request")
+	miss := redis.NewStringCmd(requestCtx, "get", "k")
+	miss.SetErr(redis.Nil)
+	ctx, err = hook.BeforeProcess(requestCtx, miss)
+	require.NoError(t, err)
+	require.NoError(t, hook.AfterProcess(ctx, miss))
+
+	cmds := []redis.Cmder{redis.NewStatusCmd(requestCtx, "set", "k", "v"), redis.NewIntCmd(requestCtx, "expire", "k", 60)}
+	cmds[1].SetErr(errors.New("connection refused"))
+	ctx, err = hook.BeforeProcessPipeline(requestCtx, cmds)
+	require.NoError(t, err)
+	require.NoError(t, hook.AfterProcessPipeline(ctx, cmds))
+	request.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+	assert.Equal(t, "redis.get", spans[0].Name())
+	assert.Equal(t, codes.Unset, spans[0].Status().Code, "键不存在不视为失败")
+	assert.Equal(t, "redis.pipeline", spans[1].Name())
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+	assert.Equal(t, request.SpanContext().SpanID(), spans[1].Parent().SpanID())
+}
+
+func TestCacheManager_WithContext(t *testing.T) {
+	type key struct{}
+	base := NewCacheManager()
+	ctx := context.WithValue(context.Background(), key{}, "request")
+
+	bound := base.WithContext(ctx)
+	assert.Equal(t, "request", bound.ctx.Value(key{}))
+	assert.Nil(t, base.ctx.Value(key{}), "不修改原缓存管理器")
+}
@@ -34,6 +34,7 @@ func (tm *TTLManager) initTTLMap() {
 	tm.ttlMap = map[string]time.Duration{
 		"user_session":     2 * time.Hour,    // 用户会话2小时
 		"user_permissions": 1 * time.Hour,    // 用户权限1小时
+		"user_limits":      5 * time.Minute,  // 用户有效限制5分钟
 		"file_preview":     30 * time.Minute, // 文件预览30分钟
 		"file_share":       1 * time.Hour,    // 文件分享1小时
 		"file_upload":      24 * time.Hour,   // 文件上传状态24小时
@@ -47,26 +48,26 @@ func (tm *TTLManager) initTTLMap() {
 		"lock":             10 * time.Minute, // 分布式锁10分钟
 		"search_result":    15 * time.Minute, // 搜索结果15分钟
 		"search_history":   24 * time.Hour,   // 搜索历史24小时
+		"clipboard":        24 * time.Hour,   // 剪贴板24小时
 		"stats_user":       10 * time.Minute, // 用户统计10分钟
 		"stats_file":       5 * time.Minute,  // 文件统计5分钟
 		"stats_system":     1 * time.Minute,  // 系统统计1分钟
 		"message":          1 * time.Hour,    // 消息缓存1小时
 		"conversation":     30 * time.Minute, // 会话缓存30分钟
 		"online_users":     5 * time.Minute,  // 在线用户5分钟
+		"reference_data":   1 * time.Hour,    // 预热的参考数据1小时
 	}
 }
 
 // GetTTL 根据缓存类型获取对应的TTL
 func (tm *TTLManager) GetTTL(cacheType string) time.Duration {
-	cfg := config.AppConfig.Cache
-
-	// 优先检查映射表中的固定TTL
+	// 优先检查映射表中的固定TTL，固定TTL不依赖配置加载
 	if ttl, exists := tm.ttlMap[cacheType]; exists {
 		return ttl
 	}
 
 	// 处理需要从配置读取的特殊类型
-	return tm.getConfigBasedTTL(cacheType, cfg)
+	return tm.getConfigBasedTTL(cacheType, config.AppConfig.Cache)
 }
 
 // getConfigBasedTTL 获取基于配置的TTL
@@ -0,0 +1,238 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// WarmLoader 参考数据加载函数
+type WarmLoader func(ctx context.Context) (interface{}, error)
+
+// WarmSource 可预热的参考数据源
+type WarmSource struct {
+	Name string        // 数据源名称，同时作为缓存键后缀
+	TTL  time.Duration // 本地和Redis中的有效期，为0时使用 reference_data 的TTL
+	Load WarmLoader    // 加载函数
+}
+
+// WarmResult 单个数据源的预热结果
+type WarmResult struct {
+	Source   string        `json:"source"`          // 数据源名称
+	Duration time.Duration `json:"duration"`        // 加载耗时
+	Error    string        `json:"error,omitempty"` // 加载失败原因
+}
+
+// WarmReport 一次预热的汇总结果
+type WarmReport struct {
+	Results  []WarmResult  `json:"results"`  // 各数据源结果(按名称排序)
+	Duration time.Duration `json:"duration"` // 总耗时
+}
+
+// Failed 返回加载失败的数据源数量
+func (r *WarmReport) Failed() int {
+	failed := 0
+	for _, result := range r.Results {
+		if result.Error != "" {
+			failed++
+		}
+	}
+	return failed
+}
+
+// warmEntry 本地缓存的参考数据
+type warmEntry struct {
+	value    interface{}
+	loadedAt time.Time
+}
+
+// Warmer 参考数据缓存预热器
+//
+// 套餐、策略、功能开关等读多写少的数据在启动时一次性加载到进程内缓存，
+// 并写入Redis供其他实例和外部工具读取，避免部署后首批请求集中回源数据库。
+// 数据过期后在下一次读取时重新加载，加载失败则继续使用旧值；
+// 数据变更后调用 Invalidate 立即清除并重新加载。
+//
+// 使用示例：
+//
+//	warmer := cache.NewWarmer(cacheManager, logger)
+//	warmer.Register(cache.WarmSource{Name: "feature_flags", Load: loadFlags})
+//	report := warmer.WarmAll(ctx)
+//	value, ok := warmer.Get(ctx, "feature_flags")
+type Warmer struct {
+	manager    *CacheManager
+	keyBuilder *KeyBuilder
+	defaultTTL time.Duration
+	logger     *zap.Logger
+	now        func() time.Time
+
+	mu      sync.RWMutex
+	sources map[string]WarmSource
+	entries map[string]warmEntry
+}
+
+// NewWarmer 创建缓存预热器
+//
+// manager 可以为nil(如Redis未初始化)，此时只维护进程内缓存
+func NewWarmer(manager *CacheManager, logger *zap.Logger) *Warmer {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &Warmer{
+		manager:    manager,
+		keyBuilder: NewKeyBuilder(),
+		defaultTTL: NewTTLManager().GetTTL("reference_data"),
+		logger:     logger,
+		now:        time.Now,
+		sources:    make(map[string]WarmSource),
+		entries:    make(map[string]warmEntry),
+	}
+}
+
+// Register 注册数据源，同名数据源会被替换
+func (w *Warmer) Register(source WarmSource) {
+	if source.TTL <= 0 {
+		source.TTL = w.defaultTTL
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.sources[source.Name] = source
+	delete(w.entries, source.Name)
+}
+
+// Sources 返回已注册的数据源名称(按名称排序)
+func (w *Warmer) Sources() []string {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	names := make([]string, 0, len(w.sources))
+	for name := range w.sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WarmAll 加载全部数据源，单个数据源失败不影响其他数据源
+func (w *Warmer) WarmAll(ctx context.Context) *WarmReport {
+	return w.WarmSources(ctx, w.Sources()...)
+}
+
+// WarmSources 加载指定的数据源，未注册的名称记为失败
+func (w *Warmer) WarmSources(ctx context.Context, names ...string) *WarmReport {
+	start := w.now()
+	report := &WarmReport{Results: make([]WarmResult, 0, len(names))}
+
+	sorted := append([]string(nil), names...)
+	sort.Strings(sorted)
+	for _, name := range sorted {
+		loadStart := w.now()
+		result := WarmResult{Source: name}
+		if _, err := w.load(ctx, name); err != nil {
+			result.Error = err.Error()
+		}
+		result.Duration = w.now().Sub(loadStart)
+		report.Results = append(report.Results, result)
+	}
+
+	report.Duration = w.now().Sub(start)
+	return report
+}
+
+// Get 读取数据源的缓存值，未加载或已过期时同步加载
+//
+// 过期后重新加载失败时返回旧值，保证数据库短暂不可用时不影响读取
+func (w *Warmer) Get(ctx context.Context, name string) (interface{}, bool) {
+	w.mu.RLock()
+	entry, cached := w.entries[name]
+	source, registered := w.sources[name]
+	w.mu.RUnlock()
+
+	if !registered {
+		return nil, false
+	}
+	if cached && w.now().Sub(entry.loadedAt) < source.TTL {
+		return entry.value, true
+	}
+
+	value, err := w.load(ctx, name)
+	if err != nil {
+		return entry.value, cached
+	}
+	return value, true
+}
+
+// Invalidate 清除数据源的本地和Redis缓存并立即重新加载，返回重新加载的结果
+func (w *Warmer) Invalidate(ctx context.Context, names ...string) *WarmReport {
+	w.mu.Lock()
+	for _, name := range names {
+		delete(w.entries, name)
+	}
+	w.mu.Unlock()
+
+	if w.manager != nil && len(names) > 0 {
+		keys := make([]string, 0, len(names))
+		for _, name := range names {
+			keys = append(keys, w.keyBuilder.ReferenceData(name))
+		}
+		if err := w.manager.Delete(keys...); err != nil {
+			w.logger.Warn("Failed to delete reference data from redis",
+				zap.Strings("sources", names),
+				zap.Error(err))
+		}
+	}
+
+	return w.WarmSources(ctx, names...)
+}
+
+// Evict 只清除数据源的本地缓存，下次读取时重新加载
+//
+// 用于响应其他实例发布的失效消息：Redis中的共享副本已由发布者更新，本实例无需重复加载
+func (w *Warmer) Evict(names ...string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(names) == 0 {
+		w.entries = make(map[string]warmEntry)
+		return
+	}
+	for _, name := range names {
+		delete(w.entries, name)
+	}
+}
+
+// load 调用加载函数并写入本地缓存和Redis
+func (w *Warmer) load(ctx context.Context, name string) (interface{}, error) {
+	w.mu.RLock()
+	source, ok := w.sources[name]
+	w.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("未注册的参考数据源: %s", name)
+	}
+
+	value, err := source.Load(ctx)
+	if err != nil {
+		w.logger.Warn("Failed to load reference data",
+			zap.String("source", name),
+			zap.Error(err))
+		return nil, fmt.Errorf("加载参考数据 %s 失败: %w", name, err)
+	}
+
+	w.mu.Lock()
+	w.entries[name] = warmEntry{value: value, loadedAt: w.now()}
+	w.mu.Unlock()
+
+	// Redis只作为共享副本，写入失败不影响本地缓存
+	if w.manager != nil {
+		if err := w.manager.SetWithTTL(w.keyBuilder.ReferenceData(name), value, source.TTL); err != nil {
+			w.logger.Warn("Failed to write reference data to redis",
+				zap.String("source", name),
+				zap.Error(err))
+		}
+	}
+	return value, nil
+}
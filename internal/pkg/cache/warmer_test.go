@@ -0,0 +1,96 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingLoader 记录调用次数的加载函数
+type countingLoader struct {
+	calls int
+	value interface{}
+	err   error
+}
+
+func (l *countingLoader) load(context.Context) (interface{}, error) {
+	l.calls++
+	if l.err != nil {
+		return nil, l.err
+	}
+	return l.value, nil
+}
+
+func TestWarmer_WarmAll(t *testing.T) {
+	ctx := context.Background()
+	plans := &countingLoader{value: []string{"free", "pro"}}
+	broken := &countingLoader{err: errors.New("db down")}
+
+	warmer := NewWarmer(nil, nil)
+	warmer.Register(WarmSource{Name: "plans", TTL: time.Minute, Load: plans.load})
+	warmer.Register(WarmSource{Name: "broken", TTL: time.Minute, Load: broken.load})
+
+	report := warmer.WarmAll(ctx)
+	require.Len(t, report.Results, 2)
+	assert.Equal(t, "broken", report.Results[0].Source)
+	assert.Contains(t, report.Results[0].Error, "db down")
+	assert.Equal(t, "plans", report.Results[1].Source)
+	assert.Empty(t, report.Results[1].Error)
+	assert.Equal(t, 1, report.Failed())
+
+	value, ok := warmer.Get(ctx, "plans")
+	require.True(t, ok)
+	assert.Equal(t, []string{"free", "pro"}, value)
+	assert.Equal(t, 1, plans.calls, "已预热的数据不应重复加载")
+
+	_, ok = warmer.Get(ctx, "broken")
+	assert.False(t, ok)
+	_, ok = warmer.Get(ctx, "unknown")
+	assert.False(t, ok)
+}
+
+func TestWarmer_ExpiryKeepsStaleValueOnFailure(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	loader := &countingLoader{value: "v1"}
+
+	warmer := NewWarmer(nil, nil)
+	warmer.now = func() time.Time { return now }
+	warmer.Register(WarmSource{Name: "flags", TTL: time.Minute, Load: loader.load})
+	warmer.WarmAll(ctx)
+
+	now = now.Add(2 * time.Minute)
+	loader.value = "v2"
+	value, ok := warmer.Get(ctx, "flags")
+	require.True(t, ok)
+	assert.Equal(t, "v2", value)
+	assert.Equal(t, 2, loader.calls)
+
+	now = now.Add(2 * time.Minute)
+	loader.err = errors.New("db down")
+	value, ok = warmer.Get(ctx, "flags")
+	require.True(t, ok)
+	assert.Equal(t, "v2", value)
+}
+
+func TestWarmer_Invalidate(t *testing.T) {
+	ctx := context.Background()
+	loader := &countingLoader{value: "v1"}
+
+	warmer := NewWarmer(nil, nil)
+	warmer.Register(WarmSource{Name: "policies", TTL: time.Hour, Load: loader.load})
+	warmer.WarmAll(ctx)
+
+	loader.value = "v2"
+	assert.Zero(t, warmer.Invalidate(ctx, "policies").Failed())
+	value, _ := warmer.Get(ctx, "policies")
+	assert.Equal(t, "v2", value)
+	assert.Equal(t, 2, loader.calls)
+
+	assert.Equal(t, 1, warmer.Invalidate(ctx, "unknown").Failed())
+	assert.Equal(t, []string{"policies"}, warmer.Sources())
+}
@@ -0,0 +1,116 @@
+// Package captcha 人机验证令牌的服务端校验
+//
+// 前端通过 reCAPTCHA、hCaptcha 或 Cloudflare Turnstile 组件取得令牌，随请求提交；
+// 服务端调用提供方的 siteverify 接口校验令牌，三者的请求和响应格式相同
+package captcha
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"cloudpan/internal/pkg/config"
+)
+
+// DefaultTimeout 访问校验接口的默认超时
+const DefaultTimeout = 5 * time.Second
+
+// maxResponseSize 校验接口响应体的大小上限
+const maxResponseSize = 64 << 10
+
+// 校验错误
+var (
+	ErrRequired = errors.New("captcha: token required") // 没有提交令牌
+	ErrInvalid  = errors.New("captcha: invalid token")  // 令牌无效、过期或已使用
+)
+
+// Verifier 人机验证令牌校验接口
+type Verifier interface {
+	// Verify 校验令牌，令牌为空返回 ErrRequired，校验不通过返回 ErrInvalid
+	Verify(ctx context.Context, token, remoteIP string) error
+}
+
+// SiteVerifier 调用 siteverify 接口校验令牌
+type SiteVerifier struct {
+	verifyURL string
+	secret    string
+	client    *http.Client
+}
+
+// NewSiteVerifier 创建siteverify校验器，client 可以为nil，此时使用 DefaultTimeout 超时的默认客户端
+func NewSiteVerifier(verifyURL, secret string, client *http.Client) (*SiteVerifier, error) {
+	if strings.TrimSpace(verifyURL) == "" || secret == "" {
+		return nil, errors.New("captcha: verify url and secret are required")
+	}
+	if client == nil {
+		client = &http.Client{Timeout: DefaultTimeout}
+	}
+	return &SiteVerifier{verifyURL: verifyURL, secret: secret, client: client}, nil
+}
+
+// FromConfig 根据配置创建校验器，未启用时返回nil
+func FromConfig(cfg config.CaptchaConfig) (Verifier, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	verifier, err := NewSiteVerifier(cfg.VerifyURL, cfg.Secret, &http.Client{Timeout: timeout})
+	if err != nil {
+		return nil, err
+	}
+	return verifier, nil
+}
+
+// siteVerifyResponse siteverify 接口响应中使用的字段
+type siteVerifyResponse struct {
+	Success    bool     `json:"success"`
+	ErrorCodes []string `json:"error-codes"`
+}
+
+// Verify 校验令牌，提供方不可用时返回其他错误，调用方应拒绝请求而不是放行
+func (v *SiteVerifier) Verify(ctx context.Context, token, remoteIP string) error {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return ErrRequired
+	}
+
+	form := url.Values{"secret": {v.secret}, "response": {token}}
+	if remoteIP != "" {
+		form.Set("remoteip", remoteIP)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.verifyURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("captcha: build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("captcha: verify request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("captcha: verify endpoint returned status %d", resp.StatusCode)
+	}
+	var result siteVerifyResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&result); err != nil {
+		return fmt.Errorf("captcha: decode response: %w", err)
+	}
+	if !result.Success {
+		if len(result.ErrorCodes) > 0 {
+			return fmt.Errorf("%w: %s", ErrInvalid, strings.Join(result.ErrorCodes, ","))
+		}
+		return ErrInvalid
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package captcha
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"cloudpan/internal/pkg/config"
+)
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "secret", r.PostForm.Get("secret"))
+		w.Header().Set("Content-Type", "application/json")
+		switch r.PostForm.Get("response") {
+		case "good":
+			assert.Equal(t, "203.0.113.9", r.PostForm.Get("remoteip"))
+			w.Write([]byte(`{"success":true}`))
+		case "broken":
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			w.Write([]byte(`{"success":false,"error-codes":["invalid-input-response"]}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestSiteVerifier_Verify(t *testing.T) {
+	server := newTestServer(t)
+	verifier, err := NewSiteVerifier(server.URL, "secret", nil)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	assert.NoError(t, verifier.Verify(ctx, "good", "203.0.113.9"))
+	assert.ErrorIs(t, verifier.Verify(ctx, "  ", "203.0.113.9"), ErrRequired)
+
+	err = verifier.Verify(ctx, "bad", "203.0.113.9")
+	assert.ErrorIs(t, err, ErrInvalid)
+	assert.Contains(t, err.Error(), "invalid-input-response")
+
+	err = verifier.Verify(ctx, "broken", "")
+	require.Error(t, err)
+	assert.False(t, errors.Is(err, ErrInvalid), "提供方不可用不是令牌无效")
+}
+
+func TestFromConfig(t *testing.T) {
+	verifier, err := FromConfig(config.CaptchaConfig{})
+	require.NoError(t, err)
+	assert.Nil(t, verifier, "未启用时不校验")
+
+	_, err = FromConfig(config.CaptchaConfig{Enabled: true, VerifyURL: "https://example.com/siteverify"})
+	assert.Error(t, err, "缺少密钥")
+
+	verifier, err = FromConfig(config.CaptchaConfig{Enabled: true, VerifyURL: "https://example.com/siteverify", Secret: "secret"})
+	require.NoError(t, err)
+	assert.NotNil(t, verifier)
+}
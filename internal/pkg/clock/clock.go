@@ -0,0 +1,75 @@
+// Package clock 可注入的时钟
+//
+// 服务通过构造函数接收 Clock，生产环境使用系统时钟，测试使用 Fake 固定时间，
+// 避免过期判断、有效期计算等逻辑依赖真实时间导致测试不稳定。
+//
+// 使用示例：
+//
+//	clk := clock.NewFake(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+//	service := share.NewCreationService(store, files, clk, nil, logger)
+//	clk.Advance(24 * time.Hour) // 模拟分享过期
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock 时钟
+type Clock interface {
+	Now() time.Time
+}
+
+// systemClock 系统时钟
+type systemClock struct{}
+
+// Now 返回系统当前时间
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// Real 返回系统时钟
+func Real() Clock {
+	return systemClock{}
+}
+
+// OrReal 返回传入的时钟，为nil时返回系统时钟，供构造函数处理可选参数
+func OrReal(c Clock) Clock {
+	if c == nil {
+		return Real()
+	}
+	return c
+}
+
+// Fake 手动控制的时钟，时间只在调用 Set 或 Advance 时变化，并发安全
+type Fake struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFake 创建停在指定时间的时钟
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+// Now 返回当前设置的时间
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Set 设置当前时间
+func (f *Fake) Set(now time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = now
+}
+
+// Advance 将时间前进指定时长，返回前进后的时间
+func (f *Fake) Advance(d time.Duration) time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+	return f.now
+}
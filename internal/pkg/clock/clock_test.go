@@ -0,0 +1,28 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrReal(t *testing.T) {
+	assert.Equal(t, Real(), OrReal(nil))
+
+	fake := NewFake(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+	assert.Same(t, fake, OrReal(fake))
+}
+
+func TestFake(t *testing.T) {
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	fake := NewFake(start)
+	assert.Equal(t, start, fake.Now())
+	assert.Equal(t, start, fake.Now(), "时间不应自行前进")
+
+	assert.Equal(t, start.Add(time.Hour), fake.Advance(time.Hour))
+	assert.Equal(t, start.Add(time.Hour), fake.Now())
+
+	fake.Set(start)
+	assert.Equal(t, start, fake.Now())
+}
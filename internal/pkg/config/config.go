@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/spf13/viper"
@@ -35,6 +36,8 @@ var (
 // 返回值：
 //   - error: 配置加载或验证失败时的错误信息
 func Load() error {
+	resetConfigSources()
+
 	// 设置基础配置
 	if err := setupViperConfig(); err != nil {
 		return fmt.Errorf("failed to setup viper config: %w", err)
@@ -74,6 +77,7 @@ func loadConfigFiles() error {
 	if err := viper.ReadInConfig(); err != nil {
 		return fmt.Errorf("failed to read default config file: %w", err)
 	}
+	recordConfigFile(viper.ConfigFileUsed())
 
 	// 加载环境特定配置
 	return loadEnvironmentConfig()
@@ -92,7 +96,9 @@ func loadEnvironmentConfig() error {
 		if !os.IsNotExist(err) {
 			return fmt.Errorf("failed to merge %s config file: %w", envConfigName, err)
 		}
+		return nil
 	}
+	recordConfigFile(viper.ConfigFileUsed())
 
 	return nil
 }
@@ -169,6 +175,8 @@ func LoadFromFile(configPath string) error {
 	if err := viper.ReadInConfig(); err != nil {
 		return fmt.Errorf("failed to read config file %s: %w", configPath, err)
 	}
+	resetConfigSources()
+	recordConfigFile(configPath)
 
 	// 支持环境变量覆盖
 	viper.AutomaticEnv()
@@ -201,6 +209,10 @@ func validateConfig(cfg *Config) error {
 		validateJWTConfig,
 		validateStorageConfig,
 		validateEmailConfig,
+		validateTracingConfig,
+		validateInactivityConfig,
+		validateSMSConfig,
+		validateLDAPConfig,
 	}
 
 	for _, validator := range validators {
@@ -254,7 +266,23 @@ func validateDatabaseConfig(cfg *Config) error {
 	if err := validateRequired("database.mysql.username", cfg.Database.MySQL.Username); err != nil {
 		return err
 	}
-	return validateRequired("database.mysql.dbname", cfg.Database.MySQL.DBName)
+	if err := validateRequired("database.mysql.dbname", cfg.Database.MySQL.DBName); err != nil {
+		return err
+	}
+	return validateIDGeneratorConfig(cfg)
+}
+
+// validateIDGeneratorConfig 验证ID生成器配置
+func validateIDGeneratorConfig(cfg *Config) error {
+	idConfig := cfg.Database.IDGenerator
+	switch strings.ToLower(idConfig.Type) {
+	case "", "uuid", "ulid":
+		return nil
+	case "snowflake":
+		return validateRange("database.id_generator.worker_id", idConfig.WorkerID, 0, 1023)
+	default:
+		return fmt.Errorf("database.id_generator.type must be one of uuid, ulid, snowflake")
+	}
 }
 
 // validateRedisConfig 验证Redis配置
@@ -277,7 +305,132 @@ func validateStorageConfig(cfg *Config) error {
 	}
 
 	if cfg.Storage.OSS.Enabled {
-		return validateOSSConfig(cfg)
+		if err := validateOSSConfig(cfg); err != nil {
+			return err
+		}
+	}
+
+	if err := validateFolderLimitsConfig(cfg); err != nil {
+		return err
+	}
+	if cfg.Storage.Versions.MaxVersions < 0 || cfg.Storage.Versions.MaxOverwriteSize < 0 {
+		return fmt.Errorf("storage.versions.max_versions and storage.versions.max_overwrite_size must not be negative")
+	}
+	if err := validateStorageHealthConfig(cfg); err != nil {
+		return err
+	}
+	if err := validateUploadTTLConfig(cfg); err != nil {
+		return err
+	}
+	if err := validateSandboxConfig(cfg); err != nil {
+		return err
+	}
+
+	switch cfg.Storage.Backend {
+	case "", "local":
+		return nil
+	case "s3":
+		return validateS3Config(cfg)
+	default:
+		return fmt.Errorf("storage.backend must be one of: local, s3")
+	}
+}
+
+// validateSandboxConfig 验证用户内容沙箱配置
+//
+// 专用域名只能是主机名，脚本来源不能包含策略分隔符或放开内联脚本
+func validateSandboxConfig(cfg *Config) error {
+	sandbox := cfg.Storage.Preview.Sandbox
+	if strings.ContainsAny(sandbox.Host, "/:; ") {
+		return fmt.Errorf("storage.preview.sandbox.host must be a host name without scheme, port or path")
+	}
+	for _, source := range sandbox.ScriptSources {
+		if source == "" || strings.ContainsAny(source, ";, ") {
+			return fmt.Errorf("storage.preview.sandbox.script_sources contains an invalid source %q", source)
+		}
+		if strings.HasPrefix(source, "'unsafe-") {
+			return fmt.Errorf("storage.preview.sandbox.script_sources must not allow %s", source)
+		}
+	}
+	return nil
+}
+
+// validateStorageHealthConfig 验证只读副本和健康检查配置
+func validateStorageHealthConfig(cfg *Config) error {
+	health := cfg.Storage.Health
+	if health.ProbeInterval < 0 || health.ProbeTimeout < 0 || health.OpenDuration < 0 || health.FailureThreshold < 0 {
+		return fmt.Errorf("storage.health intervals and failure_threshold must not be negative")
+	}
+
+	replica := cfg.Storage.Replica
+	switch replica.Backend {
+	case "":
+		return nil
+	case "local":
+		if replica.RootPath == "" {
+			return fmt.Errorf("storage.replica.root_path is required when replica backend is local")
+		}
+	case "s3":
+		if replica.S3.Endpoint == "" || replica.S3.Bucket == "" {
+			return fmt.Errorf("storage.replica.s3.endpoint and storage.replica.s3.bucket are required when replica backend is s3")
+		}
+	default:
+		return fmt.Errorf("unsupported storage replica backend: %s", replica.Backend)
+	}
+	if !health.Enabled {
+		return fmt.Errorf("storage.health.enabled must be true when storage.replica is configured")
+	}
+	return nil
+}
+
+// validateUploadTTLConfig 验证分片上传有效期配置，未配置的项使用默认值
+func validateUploadTTLConfig(cfg *Config) error {
+	ttl := cfg.Storage.Upload.TTL
+	if ttl.Default < 0 || ttl.Min < 0 || ttl.Max < 0 || ttl.IdleTimeout < 0 || ttl.MinSamples < 0 {
+		return fmt.Errorf("storage.upload.ttl durations and min_samples must not be negative")
+	}
+	if ttl.Min > 0 && ttl.Max > 0 && ttl.Min > ttl.Max {
+		return fmt.Errorf("storage.upload.ttl.min must not exceed storage.upload.ttl.max")
+	}
+	if ttl.Percentile < 0 || ttl.Percentile > 1 {
+		return fmt.Errorf("storage.upload.ttl.percentile must be between 0 and 1")
+	}
+	if ttl.Multiplier < 0 {
+		return fmt.Errorf("storage.upload.ttl.multiplier must not be negative")
+	}
+	return nil
+}
+
+// validateS3Config 验证S3兼容存储配置
+func validateS3Config(cfg *Config) error {
+	s3 := cfg.Storage.S3
+	if s3.Endpoint == "" {
+		return fmt.Errorf("storage.s3.endpoint is required when s3 backend is selected")
+	}
+	if s3.Bucket == "" {
+		return fmt.Errorf("storage.s3.bucket is required when s3 backend is selected")
+	}
+	if s3.AccessKeyID == "" || s3.SecretAccessKey == "" {
+		return fmt.Errorf("storage.s3.access_key_id and storage.s3.secret_access_key are required when s3 backend is selected")
+	}
+	// S3要求除最后一片外每片至少5MB，最多10000片
+	if s3.PartSize != 0 && (s3.PartSize < 5<<20 || s3.PartSize > 5<<30) {
+		return fmt.Errorf("storage.s3.part_size must be between 5MB and 5GB")
+	}
+	return nil
+}
+
+// validateFolderLimitsConfig 验证文件夹限制配置，0表示使用默认值
+func validateFolderLimitsConfig(cfg *Config) error {
+	folders := cfg.Storage.Folders
+	if folders.MaxDepth < 0 {
+		return fmt.Errorf("storage.folders.max_depth must not be negative")
+	}
+	if folders.MaxChildren < 0 {
+		return fmt.Errorf("storage.folders.max_children must not be negative")
+	}
+	if folders.WarnRatio < 0 || folders.WarnRatio > 1 {
+		return fmt.Errorf("storage.folders.warn_ratio must be between 0 and 1")
 	}
 	return nil
 }
@@ -307,6 +460,129 @@ func validateEmailConfig(cfg *Config) error {
 	return nil
 }
 
+// validateInactivityConfig 验证长期未登录账户处置策略配置，未启用时不检查
+func validateInactivityConfig(cfg *Config) error {
+	inactivity := cfg.User.Inactivity
+	if !inactivity.Enabled {
+		return nil
+	}
+	if inactivity.InactiveMonths <= 0 {
+		return fmt.Errorf("user.inactivity.inactive_months must be positive")
+	}
+	switch inactivity.Action {
+	case "", "none", "downgrade", "freeze":
+	default:
+		return fmt.Errorf("user.inactivity.action must be one of none, downgrade, freeze")
+	}
+	if inactivity.Action == "downgrade" && inactivity.DowngradedQuota <= 0 {
+		return fmt.Errorf("user.inactivity.downgraded_quota must be positive when action is downgrade")
+	}
+	if inactivity.DeleteAfterMonths != 0 && inactivity.DeleteAfterMonths <= inactivity.InactiveMonths {
+		return fmt.Errorf("user.inactivity.delete_after_months must be greater than inactive_months")
+	}
+	for _, days := range inactivity.WarningDays {
+		if days <= 0 {
+			return fmt.Errorf("user.inactivity.warning_days must be positive")
+		}
+	}
+	return nil
+}
+
+// validateSMSConfig 验证短信服务配置，未启用时不检查
+func validateSMSConfig(cfg *Config) error {
+	sms := cfg.ThirdParty.SMS
+	if !sms.Enabled {
+		return nil
+	}
+	if sms.HourlyLimit < 0 {
+		return fmt.Errorf("third_party.sms.hourly_limit must not be negative")
+	}
+	if code := strings.TrimPrefix(sms.DefaultCountryCode, "+"); code != "" {
+		if len(code) > 3 || strings.Trim(code, "0123456789") != "" || code[0] == '0' {
+			return fmt.Errorf("third_party.sms.default_country_code must be 1-3 digits")
+		}
+	}
+	switch sms.Provider {
+	case "aliyun":
+		if sms.Aliyun.AccessKeyID == "" || sms.Aliyun.AccessKeySecret == "" {
+			return fmt.Errorf("third_party.sms.aliyun access key is required when provider is aliyun")
+		}
+		if sms.Aliyun.SignName == "" || sms.Aliyun.TemplateCode == "" {
+			return fmt.Errorf("third_party.sms.aliyun.sign_name and template_code are required when provider is aliyun")
+		}
+	case "twilio":
+		if sms.Twilio.AccountSID == "" || sms.Twilio.AuthToken == "" {
+			return fmt.Errorf("third_party.sms.twilio.account_sid and auth_token are required when provider is twilio")
+		}
+		if sms.Twilio.From == "" && sms.Twilio.MessagingServiceSID == "" {
+			return fmt.Errorf("third_party.sms.twilio.from or messaging_service_sid is required when provider is twilio")
+		}
+	default:
+		return fmt.Errorf("third_party.sms.provider must be one of aliyun, twilio")
+	}
+	return nil
+}
+
+// validateLDAPConfig 验证LDAP认证配置，未启用时不检查
+func validateLDAPConfig(cfg *Config) error {
+	ldap := cfg.LDAP
+	if !ldap.Enabled {
+		return nil
+	}
+	if !strings.HasPrefix(ldap.URL, "ldap://") && !strings.HasPrefix(ldap.URL, "ldaps://") {
+		return fmt.Errorf("ldap.url must start with ldap:// or ldaps://")
+	}
+	if ldap.BaseDN == "" {
+		return fmt.Errorf("ldap.base_dn is required when ldap is enabled")
+	}
+	if ldap.BindDN != "" && ldap.BindPassword == "" {
+		return fmt.Errorf("ldap.bind_password is required when ldap.bind_dn is set")
+	}
+	if ldap.UserFilter != "" && !strings.Contains(ldap.UserFilter, "{username}") {
+		return fmt.Errorf("ldap.user_filter must contain {username}")
+	}
+	if ldap.Timeout < 0 || ldap.StorageQuota < 0 {
+		return fmt.Errorf("ldap.timeout and ldap.storage_quota must not be negative")
+	}
+	roles := []string{"user", "moderator", "admin"}
+	if ldap.DefaultRole != "" && !slices.Contains(roles, ldap.DefaultRole) {
+		return fmt.Errorf("ldap.default_role must be one of user, moderator, admin")
+	}
+	for group, role := range ldap.RoleMappings {
+		if !slices.Contains(roles, role) {
+			return fmt.Errorf("ldap.role_mappings[%s] must be one of user, moderator, admin", group)
+		}
+	}
+	for group, teamID := range ldap.TeamMappings {
+		if teamID == 0 {
+			return fmt.Errorf("ldap.team_mappings[%s] must be a team id", group)
+		}
+	}
+	return nil
+}
+
+// validateTracingConfig 验证分布式追踪配置，未启用追踪时不检查
+func validateTracingConfig(cfg *Config) error {
+	tracing := cfg.Monitoring.Tracing
+	if !tracing.Enabled {
+		return nil
+	}
+	switch strings.ToLower(tracing.Exporter) {
+	case "", "otlp", "jaeger", "stdout":
+	default:
+		return fmt.Errorf("monitoring.tracing.exporter must be one of otlp, jaeger, stdout")
+	}
+	switch strings.ToLower(tracing.Protocol) {
+	case "", "http", "grpc":
+	default:
+		return fmt.Errorf("monitoring.tracing.protocol must be one of http, grpc")
+	}
+	if tracing.SampleRatio < 0 || tracing.SampleRatio > 1 {
+		return fmt.Errorf("monitoring.tracing.sample_ratio must be between 0 and 1")
+	}
+	return nil
+}
+
 // createDirectories 创建必要的目录
 func createDirectories(cfg *Config) error {
 	directories := collectDirectoriesToCreate(cfg)
@@ -607,6 +883,13 @@ func bindEnvVars() {
 	viper.BindEnv("database.mysql.password", "CLOUDPAN_DATABASE_MYSQL_PASSWORD") // #nosec G104
 	viper.BindEnv("database.mysql.dbname", "CLOUDPAN_DATABASE_MYSQL_DBNAME")     // #nosec G104
 
+	// 雪花ID的worker ID通常按实例注入
+	viper.BindEnv("database.id_generator.worker_id", "CLOUDPAN_DATABASE_ID_GENERATOR_WORKER_ID") // #nosec G104
+
+	// S3存储访问密钥通常通过环境变量注入
+	viper.BindEnv("storage.s3.access_key_id", "CLOUDPAN_STORAGE_S3_ACCESS_KEY_ID")         // #nosec G104
+	viper.BindEnv("storage.s3.secret_access_key", "CLOUDPAN_STORAGE_S3_SECRET_ACCESS_KEY") // #nosec G104
+
 	// Redis相关环境变量绑定
 	viper.BindEnv("redis.host", "CLOUDPAN_REDIS_HOST")         // #nosec G104
 	viper.BindEnv("redis.port", "CLOUDPAN_REDIS_PORT")         // #nosec G104
@@ -616,6 +899,20 @@ func bindEnvVars() {
 	// JWT相关环境变量绑定
 	viper.BindEnv("jwt.secret", "CLOUDPAN_JWT_SECRET") // #nosec G104
 
+	// 字段加密相关环境变量绑定
+	viper.BindEnv("security.encryption.active_key", "CLOUDPAN_SECURITY_ENCRYPTION_ACTIVE_KEY") // #nosec G104
+	viper.BindEnv("security.encryption.keys", "CLOUDPAN_SECURITY_ENCRYPTION_KEYS")             // #nosec G104
+
+	// 人机验证服务端密钥
+	viper.BindEnv("security.captcha.secret", "CLOUDPAN_SECURITY_CAPTCHA_SECRET") // #nosec G104
+
+	// 内容审核接口密钥
+	viper.BindEnv("security.moderation.api_key", "CLOUDPAN_SECURITY_MODERATION_API_KEY") // #nosec G104
+
+	// 备份加密相关环境变量绑定
+	viper.BindEnv("backup.active_key", "CLOUDPAN_BACKUP_ACTIVE_KEY") // #nosec G104
+	viper.BindEnv("backup.keys", "CLOUDPAN_BACKUP_KEYS")             // #nosec G104
+
 	// 邮件相关环境变量绑定
 	viper.BindEnv("email.smtp.username", "CLOUDPAN_EMAIL_SMTP_USERNAME")     // #nosec G104
 	viper.BindEnv("email.smtp.password", "CLOUDPAN_EMAIL_SMTP_PASSWORD")     // #nosec G104
@@ -628,10 +925,25 @@ func bindEnvVars() {
 	viper.BindEnv("storage.oss.endpoint", "CLOUDPAN_STORAGE_OSS_ENDPOINT")                   // #nosec G104
 	viper.BindEnv("storage.oss.region", "CLOUDPAN_STORAGE_OSS_REGION")                       // #nosec G104
 
+	// 短信服务密钥
+	viper.BindEnv("third_party.sms.aliyun.access_key_id", "CLOUDPAN_THIRD_PARTY_SMS_ALIYUN_ACCESS_KEY_ID")         // #nosec G104
+	viper.BindEnv("third_party.sms.aliyun.access_key_secret", "CLOUDPAN_THIRD_PARTY_SMS_ALIYUN_ACCESS_KEY_SECRET") // #nosec G104
+	viper.BindEnv("third_party.sms.twilio.auth_token", "CLOUDPAN_THIRD_PARTY_SMS_TWILIO_AUTH_TOKEN")               // #nosec G104
+
+	// LDAP服务账号密码
+	viper.BindEnv("ldap.bind_password", "CLOUDPAN_LDAP_BIND_PASSWORD") // #nosec G104
+
 	// 服务器相关环境变量绑定
 	viper.BindEnv("server.host", "CLOUDPAN_SERVER_HOST") // #nosec G104
 	viper.BindEnv("server.port", "CLOUDPAN_SERVER_PORT") // #nosec G104
 
+	// 维护窗口期间通过环境变量开启只读模式
+	viper.BindEnv("server.read_only", "CLOUDPAN_SERVER_READ_ONLY") // #nosec G104
+
 	// 日志相关环境变量绑定
 	viper.BindEnv("log.level", "CLOUDPAN_LOG_LEVEL") // #nosec G104
+
+	// 追踪接收端通常按部署环境注入
+	viper.BindEnv("monitoring.tracing.enabled", "CLOUDPAN_MONITORING_TRACING_ENABLED")   // #nosec G104
+	viper.BindEnv("monitoring.tracing.endpoint", "CLOUDPAN_MONITORING_TRACING_ENDPOINT") // #nosec G104
 }
@@ -688,6 +688,211 @@ func TestValidateOSSConfig(t *testing.T) {
 	}
 }
 
+func TestValidateIDGeneratorConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		idGen   IDGeneratorConfig
+		wantErr bool
+	}{
+		{name: "default", idGen: IDGeneratorConfig{}},
+		{name: "ulid", idGen: IDGeneratorConfig{Type: "ulid"}},
+		{name: "snowflake", idGen: IDGeneratorConfig{Type: "snowflake", WorkerID: 1023}},
+		{name: "snowflake worker out of range", idGen: IDGeneratorConfig{Type: "snowflake", WorkerID: 1024}, wantErr: true},
+		{name: "unknown type", idGen: IDGeneratorConfig{Type: "autoincrement"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateIDGeneratorConfig(&Config{Database: DatabaseConfig{IDGenerator: tt.idGen}})
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateTracingConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		tracing TracingConfig
+		wantErr bool
+	}{
+		{name: "disabled ignores invalid values", tracing: TracingConfig{Exporter: "zipkin"}},
+		{name: "defaults", tracing: TracingConfig{Enabled: true}},
+		{name: "jaeger grpc", tracing: TracingConfig{Enabled: true, Exporter: "jaeger", Protocol: "grpc", SampleRatio: 0.25}},
+		{name: "unknown exporter", tracing: TracingConfig{Enabled: true, Exporter: "zipkin"}, wantErr: true},
+		{name: "unknown protocol", tracing: TracingConfig{Enabled: true, Protocol: "thrift"}, wantErr: true},
+		{name: "sample ratio out of range", tracing: TracingConfig{Enabled: true, SampleRatio: 1.5}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateTracingConfig(&Config{Monitoring: MonitoringConfig{Tracing: tt.tracing}})
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateInactivityConfig(t *testing.T) {
+	tests := []struct {
+		name       string
+		inactivity InactivityConfig
+		wantErr    bool
+	}{
+		{name: "disabled ignores invalid values", inactivity: InactivityConfig{Action: "archive"}},
+		{name: "downgrade then delete", inactivity: InactivityConfig{Enabled: true, InactiveMonths: 12, Action: "downgrade", DowngradedQuota: 1 << 30, DeleteAfterMonths: 24, WarningDays: []int{30, 7}}},
+		{name: "downgrade without quota", inactivity: InactivityConfig{Enabled: true, InactiveMonths: 12, Action: "downgrade"}, wantErr: true},
+		{name: "missing months", inactivity: InactivityConfig{Enabled: true}, wantErr: true},
+		{name: "unknown action", inactivity: InactivityConfig{Enabled: true, InactiveMonths: 12, Action: "archive"}, wantErr: true},
+		{name: "deletion before action", inactivity: InactivityConfig{Enabled: true, InactiveMonths: 12, DeleteAfterMonths: 6}, wantErr: true},
+		{name: "non-positive warning day", inactivity: InactivityConfig{Enabled: true, InactiveMonths: 12, WarningDays: []int{0}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateInactivityConfig(&Config{User: UserConfig{Inactivity: tt.inactivity}})
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateUploadTTLConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		ttl     UploadTTLConfig
+		wantErr bool
+	}{
+		{name: "defaults", ttl: UploadTTLConfig{}},
+		{name: "adaptive", ttl: UploadTTLConfig{Default: 24 * time.Hour, Min: 2 * time.Hour, Max: 72 * time.Hour, Adaptive: true, Percentile: 0.95, Multiplier: 2, IdleTimeout: 6 * time.Hour}},
+		{name: "min above max", ttl: UploadTTLConfig{Min: 48 * time.Hour, Max: 24 * time.Hour}, wantErr: true},
+		{name: "negative idle timeout", ttl: UploadTTLConfig{IdleTimeout: -time.Hour}, wantErr: true},
+		{name: "percentile above one", ttl: UploadTTLConfig{Percentile: 95}, wantErr: true},
+		{name: "negative multiplier", ttl: UploadTTLConfig{Multiplier: -1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateUploadTTLConfig(&Config{Storage: StorageConfig{Upload: UploadConfig{TTL: tt.ttl}}})
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateSMSConfig(t *testing.T) {
+	aliyun := AliyunSMSConfig{AccessKeyID: "ak", AccessKeySecret: "sk", SignName: "CloudPan", TemplateCode: "SMS_1"}
+	tests := []struct {
+		name    string
+		sms     SMSConfig
+		wantErr bool
+	}{
+		{name: "disabled", sms: SMSConfig{Provider: "unknown"}},
+		{name: "aliyun", sms: SMSConfig{Enabled: true, Provider: "aliyun", DefaultCountryCode: "86", Aliyun: aliyun}},
+		{name: "aliyun missing template", sms: SMSConfig{Enabled: true, Provider: "aliyun", Aliyun: AliyunSMSConfig{AccessKeyID: "ak", AccessKeySecret: "sk", SignName: "CloudPan"}}, wantErr: true},
+		{name: "twilio", sms: SMSConfig{Enabled: true, Provider: "twilio", DefaultCountryCode: "+1", Twilio: TwilioSMSConfig{AccountSID: "AC1", AuthToken: "token", MessagingServiceSID: "MG1"}}},
+		{name: "twilio missing sender", sms: SMSConfig{Enabled: true, Provider: "twilio", Twilio: TwilioSMSConfig{AccountSID: "AC1", AuthToken: "token"}}, wantErr: true},
+		{name: "unknown provider", sms: SMSConfig{Enabled: true, Provider: "smtp"}, wantErr: true},
+		{name: "invalid country code", sms: SMSConfig{Enabled: true, Provider: "aliyun", DefaultCountryCode: "086", Aliyun: aliyun}, wantErr: true},
+		{name: "negative hourly limit", sms: SMSConfig{Enabled: true, Provider: "aliyun", HourlyLimit: -1, Aliyun: aliyun}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateSMSConfig(&Config{ThirdParty: ThirdPartyConfig{SMS: tt.sms}})
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateLDAPConfig(t *testing.T) {
+	valid := LDAPConfig{Enabled: true, URL: "ldaps://ldap.example.com", BaseDN: "dc=example,dc=com"}
+	with := func(modify func(*LDAPConfig)) LDAPConfig {
+		cfg := valid
+		modify(&cfg)
+		return cfg
+	}
+	tests := []struct {
+		name    string
+		ldap    LDAPConfig
+		wantErr bool
+	}{
+		{name: "disabled", ldap: LDAPConfig{URL: "http://ldap"}},
+		{name: "valid", ldap: with(func(c *LDAPConfig) {
+			c.BindDN, c.BindPassword = "cn=reader,dc=example,dc=com", "secret"
+			c.RoleMappings = map[string]string{"cn=admins,dc=example,dc=com": "admin"}
+			c.TeamMappings = map[string]uint{"cn=dev,dc=example,dc=com": 3}
+		})},
+		{name: "invalid url", ldap: with(func(c *LDAPConfig) { c.URL = "ldap.example.com" }), wantErr: true},
+		{name: "missing base dn", ldap: with(func(c *LDAPConfig) { c.BaseDN = "" }), wantErr: true},
+		{name: "bind dn without password", ldap: with(func(c *LDAPConfig) { c.BindDN = "cn=reader" }), wantErr: true},
+		{name: "filter without placeholder", ldap: with(func(c *LDAPConfig) { c.UserFilter = "(uid=alice)" }), wantErr: true},
+		{name: "unknown role", ldap: with(func(c *LDAPConfig) { c.RoleMappings = map[string]string{"cn=root": "superuser"} }), wantErr: true},
+		{name: "missing team id", ldap: with(func(c *LDAPConfig) { c.TeamMappings = map[string]uint{"cn=dev": 0} }), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateLDAPConfig(&Config{LDAP: tt.ldap})
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateStorageBackend(t *testing.T) {
+	s3 := S3StorageConfig{Endpoint: "minio.local:9000", Bucket: "files", AccessKeyID: "ak", SecretAccessKey: "sk"}
+	tests := []struct {
+		name    string
+		storage StorageConfig
+		wantErr bool
+	}{
+		{name: "default local", storage: StorageConfig{}},
+		{name: "s3", storage: StorageConfig{Backend: "s3", S3: s3}},
+		{name: "s3 missing bucket", storage: StorageConfig{Backend: "s3", S3: S3StorageConfig{Endpoint: "minio.local:9000", AccessKeyID: "ak", SecretAccessKey: "sk"}}, wantErr: true},
+		{name: "s3 part too small", storage: StorageConfig{Backend: "s3", S3: S3StorageConfig{Endpoint: s3.Endpoint, Bucket: s3.Bucket, AccessKeyID: "ak", SecretAccessKey: "sk", PartSize: 1 << 20}}, wantErr: true},
+		{name: "unknown backend", storage: StorageConfig{Backend: "ftp"}, wantErr: true},
+		{name: "folder limits", storage: StorageConfig{Folders: FolderLimitsConfig{MaxDepth: 32, MaxChildren: 5000, WarnRatio: 0.9}}},
+		{name: "negative folder depth", storage: StorageConfig{Folders: FolderLimitsConfig{MaxDepth: -1}}, wantErr: true},
+		{name: "negative folder children", storage: StorageConfig{Folders: FolderLimitsConfig{MaxChildren: -1}}, wantErr: true},
+		{name: "folder warn ratio out of range", storage: StorageConfig{Folders: FolderLimitsConfig{WarnRatio: 1.2}}, wantErr: true},
+		{name: "sandbox host", storage: StorageConfig{Preview: PreviewConfig{Sandbox: SandboxConfig{Host: "usercontent.example.com", ScriptSources: []string{"'self'", "https://cdn.example.com"}}}}},
+		{name: "sandbox host with scheme", storage: StorageConfig{Preview: PreviewConfig{Sandbox: SandboxConfig{Host: "https://usercontent.example.com"}}}, wantErr: true},
+		{name: "sandbox inline scripts", storage: StorageConfig{Preview: PreviewConfig{Sandbox: SandboxConfig{ScriptSources: []string{"'unsafe-inline'"}}}}, wantErr: true},
+		{name: "sandbox policy injection", storage: StorageConfig{Preview: PreviewConfig{Sandbox: SandboxConfig{ScriptSources: []string{"'self'; sandbox allow-same-origin"}}}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateStorageConfig(&Config{Storage: tt.storage})
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
 // TestCreateDirectories 测试目录创建
 func TestCreateDirectories(t *testing.T) {
 	// 创建临时目录用于测试
@@ -0,0 +1,273 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// 配置项来源
+const (
+	SourceDefault = "default" // 配置文件和环境变量均未设置，使用默认值
+	SourceFile    = "file"    // 来自配置文件
+	SourceEnv     = "env"     // 来自环境变量(含.env文件)
+)
+
+// secretMask 敏感配置项的掩码
+const secretMask = "******"
+
+// secretConfigKeys 值需要掩码的配置项名称(按mapstructure名称匹配最后一级)
+var secretConfigKeys = map[string]bool{
+	"password":          true,
+	"secret":            true,
+	"keys":              true,
+	"access_key_id":     true,
+	"access_key_secret": true,
+	"secret_access_key": true,
+	"app_secret":        true,
+	"api_key":           true,
+}
+
+// isSecretConfigKey 判断配置项是否为敏感信息
+func isSecretConfigKey(name string) bool {
+	return secretConfigKeys[name] ||
+		strings.HasSuffix(name, "_password") ||
+		strings.HasSuffix(name, "_secret") ||
+		strings.HasSuffix(name, "_token")
+}
+
+// configFileSource 已加载的配置文件及其中设置的配置项
+type configFileSource struct {
+	path string
+	keys map[string]bool
+}
+
+var (
+	configSourcesMu sync.RWMutex
+	configSources   []configFileSource
+)
+
+// resetConfigSources 清空已记录的配置文件，重新加载配置前调用
+func resetConfigSources() {
+	configSourcesMu.Lock()
+	defer configSourcesMu.Unlock()
+	configSources = nil
+}
+
+// recordConfigFile 记录已加载的配置文件，后记录的文件优先
+//
+// 单独读取一次文件以获得该文件设置的配置项，合并后的viper无法区分配置项来自哪个文件
+func recordConfigFile(path string) {
+	if path == "" {
+		return
+	}
+	keys := make(map[string]bool)
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err == nil {
+		for _, key := range v.AllKeys() {
+			keys[key] = true
+		}
+	}
+
+	configSourcesMu.Lock()
+	defer configSourcesMu.Unlock()
+	configSources = append(configSources, configFileSource{path: path, keys: keys})
+}
+
+// EffectiveConfig 生效配置的脱敏视图，用于排查线上配置问题
+//
+// 密码、密钥等敏感配置项只显示是否已设置，不返回原值
+type EffectiveConfig struct {
+	Environment string                 `json:"environment"` // 当前环境(GO_ENV)
+	Files       []string               `json:"files"`       // 按加载顺序排列的配置文件，后加载的覆盖先加载的
+	Values      map[string]interface{} `json:"values"`      // 按配置文件结构组织的生效配置
+	Sources     map[string]string      `json:"sources"`     // 每个配置项的来源，格式为 default、file:路径 或 env:变量名
+}
+
+// Effective 生成配置的脱敏视图
+//
+// 使用示例：
+//
+//	view := config.Effective(config.AppConfig)
+//	port := view.Values["server"].(map[string]interface{})["port"]
+//	source := view.Sources["jwt.secret"] // 例如 env:CLOUDPAN_JWT_SECRET
+func Effective(cfg *Config) *EffectiveConfig {
+	configSourcesMu.RLock()
+	files := make([]configFileSource, len(configSources))
+	copy(files, configSources)
+	configSourcesMu.RUnlock()
+
+	view := &EffectiveConfig{
+		Environment: getEnvironment(),
+		Files:       make([]string, 0, len(files)),
+		Values:      map[string]interface{}{},
+		Sources:     map[string]string{},
+	}
+	for _, file := range files {
+		view.Files = append(view.Files, file.path)
+	}
+	if cfg == nil {
+		return view
+	}
+
+	walker := &effectiveConfigWalker{files: files, sources: view.Sources}
+	if values, ok := walker.value("", reflect.ValueOf(*cfg)).(map[string]interface{}); ok {
+		view.Values = values
+	}
+	return view
+}
+
+// Section 返回指定顶级配置段的脱敏配置，不存在时返回nil
+func (e *EffectiveConfig) Section(name string) map[string]interface{} {
+	section, _ := e.Values[name].(map[string]interface{})
+	return section
+}
+
+// effectiveConfigWalker 遍历配置结构体，生成脱敏后的值并记录来源
+type effectiveConfigWalker struct {
+	files   []configFileSource
+	sources map[string]string
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// value 转换配置值，结构体和映射转换为以配置名为键的映射
+func (w *effectiveConfigWalker) value(key string, v reflect.Value) interface{} {
+	if v.Type() == durationType {
+		w.recordSource(key)
+		return time.Duration(v.Int()).String()
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			w.recordSource(key)
+			return nil
+		}
+		return w.value(key, v.Elem())
+	case reflect.Struct:
+		fields := make(map[string]interface{}, v.NumField())
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			name := configFieldName(field)
+			if name == "" {
+				continue
+			}
+			fields[name] = w.value(joinConfigKey(key, name), v.Field(i))
+		}
+		return fields
+	case reflect.Map:
+		entries := make(map[string]interface{}, v.Len())
+		mapKeys := v.MapKeys()
+		sort.Slice(mapKeys, func(i, j int) bool {
+			return fmt.Sprint(mapKeys[i].Interface()) < fmt.Sprint(mapKeys[j].Interface())
+		})
+		for _, mapKey := range mapKeys {
+			name := fmt.Sprint(mapKey.Interface())
+			entries[name] = w.value(joinConfigKey(key, name), v.MapIndex(mapKey))
+		}
+		if len(mapKeys) == 0 {
+			w.recordSource(key)
+		}
+		return entries
+	}
+
+	w.recordSource(key)
+	if isSecretConfigKey(lastConfigKey(key)) {
+		return maskConfigValue(v)
+	}
+	return v.Interface()
+}
+
+// recordSource 记录配置项来源：环境变量优先于配置文件，后加载的配置文件优先
+func (w *effectiveConfigWalker) recordSource(key string) {
+	envName := "CLOUDPAN_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+	if _, ok := os.LookupEnv(envName); ok {
+		w.sources[key] = SourceEnv + ":" + envName
+		return
+	}
+	for i := len(w.files) - 1; i >= 0; i-- {
+		if w.files[i].hasKey(key) {
+			w.sources[key] = SourceFile + ":" + w.files[i].path
+			return
+		}
+	}
+	w.sources[key] = SourceDefault
+}
+
+// hasKey 判断配置文件是否设置了该配置项(映射类配置项按前缀匹配)
+func (f configFileSource) hasKey(key string) bool {
+	if f.keys[key] {
+		return true
+	}
+	prefix := key + "."
+	for k := range f.keys {
+		if strings.HasPrefix(k, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// maskConfigValue 掩码敏感配置值，空值保持为空以便确认是否已配置
+//
+// 密钥列表格式为 版本:密钥，保留版本便于确认密钥轮换状态
+func maskConfigValue(v reflect.Value) interface{} {
+	switch v.Kind() {
+	case reflect.String:
+		if v.String() == "" {
+			return ""
+		}
+		return secretMask
+	case reflect.Slice, reflect.Array:
+		masked := make([]string, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			item := fmt.Sprint(v.Index(i).Interface())
+			if version, _, found := strings.Cut(item, ":"); found {
+				masked = append(masked, version+":"+secretMask)
+				continue
+			}
+			masked = append(masked, secretMask)
+		}
+		return masked
+	}
+	if v.IsZero() {
+		return v.Interface()
+	}
+	return secretMask
+}
+
+// configFieldName 返回字段的配置名，未导出或忽略的字段返回空字符串
+func configFieldName(field reflect.StructField) string {
+	if field.PkgPath != "" {
+		return ""
+	}
+	name := strings.Split(field.Tag.Get("mapstructure"), ",")[0]
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		name = strings.ToLower(field.Name)
+	}
+	return name
+}
+
+// joinConfigKey 拼接配置项路径
+func joinConfigKey(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+// lastConfigKey 返回配置项路径的最后一级
+func lastConfigKey(key string) string {
+	return key[strings.LastIndex(key, ".")+1:]
+}
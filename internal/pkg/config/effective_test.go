@@ -0,0 +1,94 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEffective(t *testing.T) {
+	cfg := &Config{
+		App:    App{Name: "cloudpan"},
+		Server: ServerConfig{Port: 8080, ReadTimeout: 30 * time.Second},
+		Database: DatabaseConfig{
+			MySQL: MySQLConfig{Host: "db", Password: "db-password"},
+		},
+		Redis: RedisConfig{Password: ""},
+		JWT:   JWTConfig{Secret: "jwt-secret"},
+		Security: SecurityConfig{
+			Encryption: EncryptionConfig{ActiveKey: "v2", Keys: []string{"v1:AAAA", "v2:BBBB"}},
+		},
+		Jobs: JobsConfig{Pools: map[string]JobPoolConfig{"thumbnail": {}}},
+	}
+
+	// 未加载配置文件时所有配置项来源为默认值
+	resetConfigSources()
+	view := Effective(cfg)
+
+	assert.Equal(t, "cloudpan", view.Section("app")["name"])
+	assert.Equal(t, "30s", view.Section("server")["read_timeout"])
+
+	// 敏感配置项掩码，未设置的保持为空
+	mysql := view.Section("database")["mysql"].(map[string]interface{})
+	assert.Equal(t, "db", mysql["host"])
+	assert.Equal(t, secretMask, mysql["password"])
+	assert.Equal(t, "", view.Section("redis")["password"])
+	assert.Equal(t, secretMask, view.Section("jwt")["secret"])
+	encryption := view.Section("security")["encryption"].(map[string]interface{})
+	assert.Equal(t, "v2", encryption["active_key"])
+	assert.Equal(t, []string{"v1:" + secretMask, "v2:" + secretMask}, encryption["keys"])
+
+	// 映射类配置按键展开
+	pools := view.Section("jobs")["pools"].(map[string]interface{})
+	assert.Contains(t, pools, "thumbnail")
+	assert.Contains(t, view.Sources, "jobs.pools.thumbnail.concurrency")
+
+	assert.Equal(t, SourceDefault, view.Sources["server.port"])
+	assert.NotNil(t, Effective(nil).Values)
+}
+
+func TestEffectiveSources(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "config.yaml")
+	configContent := `
+app:
+  name: "test-app"
+  version: "1.0.0"
+  env: "test"
+server:
+  host: "localhost"
+  port: 8080
+database:
+  mysql:
+    host: "localhost"
+    username: "test"
+    dbname: "test_db"
+redis:
+  host: "localhost"
+jwt:
+  secret: "this_is_a_very_long_secret_key_for_testing_purposes_123456"
+storage:
+  local:
+    enabled: true
+    root_path: "` + t.TempDir() + `"
+email:
+  smtp:
+    host: "smtp.test.com"
+    from_email: "test@test.com"
+`
+	require.NoError(t, os.WriteFile(tempFile, []byte(configContent), 0644))
+	t.Setenv("CLOUDPAN_SERVER_HOST", "0.0.0.0")
+
+	AppConfig = nil
+	require.NoError(t, LoadFromFile(tempFile))
+
+	view := Effective(AppConfig)
+	assert.Equal(t, []string{tempFile}, view.Files)
+	assert.Equal(t, SourceFile+":"+tempFile, view.Sources["server.port"])
+	assert.Equal(t, SourceEnv+":CLOUDPAN_SERVER_HOST", view.Sources["server.host"])
+	assert.Equal(t, SourceDefault, view.Sources["server.read_timeout"])
+	assert.Equal(t, secretMask, view.Section("jwt")["secret"])
+}
@@ -4,22 +4,30 @@ import "time"
 
 // Config 应用配置结构体
 type Config struct {
-	App        App              `yaml:"app" mapstructure:"app"`
-	Server     ServerConfig     `yaml:"server" mapstructure:"server"`
-	Database   DatabaseConfig   `yaml:"database" mapstructure:"database"`
-	Redis      RedisConfig      `yaml:"redis" mapstructure:"redis"`
-	JWT        JWTConfig        `yaml:"jwt" mapstructure:"jwt"`
-	Storage    StorageConfig    `yaml:"storage" mapstructure:"storage"`
-	User       UserConfig       `yaml:"user" mapstructure:"user"`
-	Email      EmailConfig      `yaml:"email" mapstructure:"email"`
-	Security   SecurityConfig   `yaml:"security" mapstructure:"security"`
-	Log        LogConfig        `yaml:"log" mapstructure:"log"`
-	Cache      CacheConfig      `yaml:"cache" mapstructure:"cache"`
-	Queue      QueueConfig      `yaml:"queue" mapstructure:"queue"`
-	WebSocket  WebSocketConfig  `yaml:"websocket" mapstructure:"websocket"`
-	Monitoring MonitoringConfig `yaml:"monitoring" mapstructure:"monitoring"`
-	I18n       I18nConfig       `yaml:"i18n" mapstructure:"i18n"`
-	ThirdParty ThirdPartyConfig `yaml:"third_party" mapstructure:"third_party"`
+	App         App               `yaml:"app" mapstructure:"app"`
+	Server      ServerConfig      `yaml:"server" mapstructure:"server"`
+	Database    DatabaseConfig    `yaml:"database" mapstructure:"database"`
+	Redis       RedisConfig       `yaml:"redis" mapstructure:"redis"`
+	JWT         JWTConfig         `yaml:"jwt" mapstructure:"jwt"`
+	Storage     StorageConfig     `yaml:"storage" mapstructure:"storage"`
+	Share       ShareConfig       `yaml:"share" mapstructure:"share"`
+	User        UserConfig        `yaml:"user" mapstructure:"user"`
+	Email       EmailConfig       `yaml:"email" mapstructure:"email"`
+	Security    SecurityConfig    `yaml:"security" mapstructure:"security"`
+	Log         LogConfig         `yaml:"log" mapstructure:"log"`
+	Cache       CacheConfig       `yaml:"cache" mapstructure:"cache"`
+	Queue       QueueConfig       `yaml:"queue" mapstructure:"queue"`
+	Jobs        JobsConfig        `yaml:"jobs" mapstructure:"jobs"`
+	WebSocket   WebSocketConfig   `yaml:"websocket" mapstructure:"websocket"`
+	Monitoring  MonitoringConfig  `yaml:"monitoring" mapstructure:"monitoring"`
+	Maintenance MaintenanceConfig `yaml:"maintenance" mapstructure:"maintenance"`
+	Backup      BackupConfig      `yaml:"backup" mapstructure:"backup"`
+	Automation  AutomationConfig  `yaml:"automation" mapstructure:"automation"`
+	Team        TeamConfig        `yaml:"team" mapstructure:"team"`
+	SSO         SSOConfig         `yaml:"sso" mapstructure:"sso"`
+	LDAP        LDAPConfig        `yaml:"ldap" mapstructure:"ldap"`
+	I18n        I18nConfig        `yaml:"i18n" mapstructure:"i18n"`
+	ThirdParty  ThirdPartyConfig  `yaml:"third_party" mapstructure:"third_party"`
 }
 
 // App 应用配置
@@ -38,11 +46,19 @@ type ServerConfig struct {
 	ReadTimeout    time.Duration `yaml:"read_timeout" mapstructure:"read_timeout"`
 	WriteTimeout   time.Duration `yaml:"write_timeout" mapstructure:"write_timeout"`
 	MaxHeaderBytes int           `yaml:"max_header_bytes" mapstructure:"max_header_bytes"`
+	ReadOnly       bool          `yaml:"read_only" mapstructure:"read_only"` // 只读模式：修改数据的接口返回503，登录和浏览下载不受影响，用于数据库维护和迁移
 }
 
 // DatabaseConfig 数据库配置
 type DatabaseConfig struct {
-	MySQL MySQLConfig `yaml:"mysql" mapstructure:"mysql"`
+	MySQL       MySQLConfig       `yaml:"mysql" mapstructure:"mysql"`
+	IDGenerator IDGeneratorConfig `yaml:"id_generator" mapstructure:"id_generator"`
+}
+
+// IDGeneratorConfig 对外标识符(UUID列、分享码)生成配置
+type IDGeneratorConfig struct {
+	Type     string `yaml:"type" mapstructure:"type"`           // uuid(默认)/ulid/snowflake
+	WorkerID int    `yaml:"worker_id" mapstructure:"worker_id"` // 雪花算法worker ID(0-1023)，每个实例必须不同
 }
 
 // MySQLConfig MySQL配置
@@ -89,8 +105,168 @@ type JWTConfig struct {
 
 // StorageConfig 存储配置
 type StorageConfig struct {
-	Local LocalStorageConfig `yaml:"local" mapstructure:"local"`
-	OSS   OSSStorageConfig   `yaml:"oss" mapstructure:"oss"`
+	Backend  string              `yaml:"backend" mapstructure:"backend"` // 文件存储后端(local/s3)，为空时使用本地存储
+	Local    LocalStorageConfig  `yaml:"local" mapstructure:"local"`
+	OSS      OSSStorageConfig    `yaml:"oss" mapstructure:"oss"`
+	S3       S3StorageConfig     `yaml:"s3" mapstructure:"s3"`
+	Upload   UploadConfig        `yaml:"upload" mapstructure:"upload"`
+	Export   ExportConfig        `yaml:"export" mapstructure:"export"`
+	Direct   DirectUploadConfig  `yaml:"direct_upload" mapstructure:"direct_upload"`
+	Archive  ArchiveConfig       `yaml:"archive" mapstructure:"archive"`
+	Trash    TrashConfig         `yaml:"trash" mapstructure:"trash"`
+	Transfer TransferConfig      `yaml:"transfer" mapstructure:"transfer"`
+	Preview  PreviewConfig       `yaml:"preview" mapstructure:"preview"`
+	Folders  FolderLimitsConfig  `yaml:"folders" mapstructure:"folders"`
+	Versions VersionsConfig      `yaml:"versions" mapstructure:"versions"`
+	Replica  ReplicaConfig       `yaml:"replica" mapstructure:"replica"`
+	Health   StorageHealthConfig `yaml:"health" mapstructure:"health"`
+}
+
+// ReplicaConfig 只读副本存储配置
+//
+// 副本由存储服务的跨区域复制或同步任务保持与主存储相同的对象路径，本服务只从副本读取、从不写入；
+// 主存储读取失败或熔断时下载和预览改从副本读取
+type ReplicaConfig struct {
+	Backend  string          `yaml:"backend" mapstructure:"backend"`     // 副本存储后端(local/s3)，为空时不使用副本
+	RootPath string          `yaml:"root_path" mapstructure:"root_path"` // 本地副本的根目录
+	S3       S3StorageConfig `yaml:"s3" mapstructure:"s3"`               // S3兼容副本的连接配置
+}
+
+// StorageHealthConfig 存储后端健康检查配置
+//
+// 启用后定期探测主存储和副本，连续失败达到阈值时熔断该后端：熔断期间写入直接失败，读取切换到副本，
+// 没有可用副本时立即返回存储不可用错误，不再等待超时；熔断时长过后放行请求试探，成功后恢复
+type StorageHealthConfig struct {
+	Enabled          bool          `yaml:"enabled" mapstructure:"enabled"`                     // 是否启用健康检查和读故障切换
+	ProbeInterval    time.Duration `yaml:"probe_interval" mapstructure:"probe_interval"`       // 探测间隔，默认15秒
+	ProbeTimeout     time.Duration `yaml:"probe_timeout" mapstructure:"probe_timeout"`         // 单次探测超时，默认5秒
+	FailureThreshold int           `yaml:"failure_threshold" mapstructure:"failure_threshold"` // 连续失败多少次后熔断，默认3
+	OpenDuration     time.Duration `yaml:"open_duration" mapstructure:"open_duration"`         // 熔断后多久放行请求试探，默认30秒
+}
+
+// VersionsConfig 文件历史版本配置
+//
+// 覆盖上传和恢复版本时文件原来的内容保留为历史版本，历史版本占用用户的存储空间；
+// 超出保留个数的最早版本连同存储对象一起删除并释放存储空间
+type VersionsConfig struct {
+	MaxVersions      int   `yaml:"max_versions" mapstructure:"max_versions"`             // 每个文件保留的历史版本数，默认10
+	MaxOverwriteSize int64 `yaml:"max_overwrite_size" mapstructure:"max_overwrite_size"` // 单次请求覆盖上传的最大文件大小(字节)，默认1GB
+}
+
+// FolderLimitsConfig 文件夹层级和子项数限制
+//
+// 新建文件夹、移动、复制和上传时检查，超过限制的操作被拒绝；已有数据不受影响，由管理员报告列出
+type FolderLimitsConfig struct {
+	MaxDepth    int     `yaml:"max_depth" mapstructure:"max_depth"`       // 文件夹最大嵌套层级(根目录下的文件夹为第1层)，默认64
+	MaxChildren int     `yaml:"max_children" mapstructure:"max_children"` // 单个文件夹(含根目录)的最大直接子项数，默认100000
+	WarnRatio   float64 `yaml:"warn_ratio" mapstructure:"warn_ratio"`     // 管理员报告列出达到限制该比例的文件夹，默认0.8
+}
+
+// PreviewConfig 文件预览配置
+//
+// 上传完成后在后台为图片(以及配置了渲染命令时的PDF首页)生成缩略图和预览图，存入文件存储后端
+type PreviewConfig struct {
+	Enabled       bool          `yaml:"enabled" mapstructure:"enabled"`                 // 是否启用预览生成
+	ThumbnailSize int           `yaml:"thumbnail_size" mapstructure:"thumbnail_size"`   // 缩略图最长边(像素)，默认256
+	PreviewSize   int           `yaml:"preview_size" mapstructure:"preview_size"`       // 预览图最长边(像素)，默认1024
+	MaxSourceSize int64         `yaml:"max_source_size" mapstructure:"max_source_size"` // 生成预览的源文件大小上限(字节)，默认50MB
+	MaxPixels     int           `yaml:"max_pixels" mapstructure:"max_pixels"`           // 允许解码的最大像素数，默认4000万
+	PDFCommand    string        `yaml:"pdf_command" mapstructure:"pdf_command"`         // PDF首页渲染命令(如pdftoppm)，为空时不生成PDF预览
+	PDFTimeout    time.Duration `yaml:"pdf_timeout" mapstructure:"pdf_timeout"`         // PDF渲染超时，默认30秒
+	Sandbox       SandboxConfig `yaml:"sandbox" mapstructure:"sandbox"`                 // 预览和下载等用户内容接口的沙箱配置
+}
+
+// SandboxConfig 用户内容沙箱配置
+//
+// 预览、缩略图和下载接口返回用户上传的内容，响应始终带有 nosniff 和 sandbox 内容安全策略；
+// 配置专用域名后这些接口只响应该域名的请求，使用户内容与主站不同源
+type SandboxConfig struct {
+	Host          string   `yaml:"host" mapstructure:"host"`                     // 用户内容专用域名(如usercontent.example.com)，为空时与主站同域名
+	ScriptSources []string `yaml:"script_sources" mapstructure:"script_sources"` // 代码高亮允许加载的脚本来源，为空时禁止执行脚本；HTML类内容始终禁止脚本
+	DisableHTML   bool     `yaml:"disable_html" mapstructure:"disable_html"`     // 禁止以HTML、SVG、XML等内容类型返回用户内容，一律按二进制流返回
+}
+
+// TrashConfig 回收站配置
+//
+// 删除的文件在保留期内可以恢复，过期后由维护任务彻底删除并释放存储配额
+type TrashConfig struct {
+	Retention time.Duration `yaml:"retention" mapstructure:"retention"` // 回收站保留时长，用户未自定义时使用
+	// 用户可自定义的保留时长范围，默认为1天到retention
+	MinRetention time.Duration `yaml:"min_retention" mapstructure:"min_retention"`
+	MaxRetention time.Duration `yaml:"max_retention" mapstructure:"max_retention"`
+	// 清空回收站时项目数超过该值在后台执行，默认100
+	EmptyAsyncThreshold int `yaml:"empty_async_threshold" mapstructure:"empty_async_threshold"`
+	// 文件记录彻底删除后存储对象的额外保留时长，保留期内管理员可以从存储对象恢复文件；0表示随记录立即删除
+	ContentRetention time.Duration `yaml:"content_retention" mapstructure:"content_retention"`
+	// 按套餐(用户资料中的plan)设置的存储对象保留时长，覆盖content_retention
+	ContentRetentionPlans map[string]time.Duration `yaml:"content_retention_plans" mapstructure:"content_retention_plans"`
+	// 按租户(用户资料中的tenant)设置的存储对象保留时长，优先于套餐
+	ContentRetentionTenants map[string]time.Duration `yaml:"content_retention_tenants" mapstructure:"content_retention_tenants"`
+}
+
+// TransferConfig 文件所有权转移配置
+type TransferConfig struct {
+	Expiry   time.Duration `yaml:"expiry" mapstructure:"expiry"`       // 转移等待接受的有效期，默认7天
+	MaxItems int           `yaml:"max_items" mapstructure:"max_items"` // 单次转移的最大文件数(含文件夹)，默认10000
+}
+
+// ArchiveConfig 归档存储配置
+//
+// 长期未访问的对象存储文件转换为归档存储类型，读取前需要先发起恢复
+type ArchiveConfig struct {
+	Enabled         bool          `yaml:"enabled" mapstructure:"enabled"`                   // 是否启用归档(需同时启用OSS)
+	StorageClass    string        `yaml:"storage_class" mapstructure:"storage_class"`       // 归档存储类型，如 GLACIER、DEEP_ARCHIVE
+	TransitionAfter time.Duration `yaml:"transition_after" mapstructure:"transition_after"` // 文件多久未访问后归档
+	MinSize         int64         `yaml:"min_size" mapstructure:"min_size"`                 // 参与归档的最小文件大小(字节)
+	ScanInterval    time.Duration `yaml:"scan_interval" mapstructure:"scan_interval"`       // 归档扫描间隔
+	BatchSize       int           `yaml:"batch_size" mapstructure:"batch_size"`             // 每次扫描最多归档的文件数
+	RestoreDays     int           `yaml:"restore_days" mapstructure:"restore_days"`         // 默认恢复保留天数
+	MaxRestoreDays  int           `yaml:"max_restore_days" mapstructure:"max_restore_days"` // 用户可申请的最长恢复保留天数
+	RestoreTier     string        `yaml:"restore_tier" mapstructure:"restore_tier"`         // 恢复速度等级(Expedited/Standard/Bulk)
+}
+
+// DirectUploadConfig 浏览器直传对象存储配置
+//
+// 启用后大文件由浏览器使用预签名POST表单直接上传到OSS/S3，不经过应用服务器中转
+type DirectUploadConfig struct {
+	Enabled        bool          `yaml:"enabled" mapstructure:"enabled"`                 // 是否启用直传(需同时启用OSS)
+	MinSize        int64         `yaml:"min_size" mapstructure:"min_size"`               // 允许直传的最小文件大小(字节)
+	MaxSize        int64         `yaml:"max_size" mapstructure:"max_size"`               // 允许直传的最大文件大小(字节)
+	URLExpiry      time.Duration `yaml:"url_expiry" mapstructure:"url_expiry"`           // 上传凭证有效期
+	KeyPrefix      string        `yaml:"key_prefix" mapstructure:"key_prefix"`           // 对象键前缀
+	AllowedOrigins []string      `yaml:"allowed_origins" mapstructure:"allowed_origins"` // 允许发起直传的页面来源(需与存储桶CORS一致)，为空时不限制
+}
+
+// ExportConfig 文件夹导出配置
+type ExportConfig struct {
+	MaxConcurrentPerUser int   `yaml:"max_concurrent_per_user" mapstructure:"max_concurrent_per_user"` // 每个用户同时进行的导出数
+	MaxConcurrent        int   `yaml:"max_concurrent" mapstructure:"max_concurrent"`                   // 全局同时进行的导出数
+	MaxFiles             int   `yaml:"max_files" mapstructure:"max_files"`                             // 单次导出最大文件数
+	MaxTotalSize         int64 `yaml:"max_total_size" mapstructure:"max_total_size"`                   // 单次导出最大总大小(字节)
+}
+
+// UploadConfig 上传配置
+type UploadConfig struct {
+	ChunkSize        int64 `yaml:"chunk_size" mapstructure:"chunk_size"`               // 推荐分片大小
+	MaxParallelism   int   `yaml:"max_parallelism" mapstructure:"max_parallelism"`     // 客户端最大并行上传分片数
+	MergeParallelism int   `yaml:"merge_parallelism" mapstructure:"merge_parallelism"` // 服务端合并时并行预读分片数
+
+	TTL UploadTTLConfig `yaml:"ttl" mapstructure:"ttl"` // 未合并上传的有效期
+}
+
+// UploadTTLConfig 分片上传有效期配置
+//
+// 启用自适应后按客户端类型观察到的上传完成耗时调整新上传的有效期，限制在min和max之间；
+// 上传过程中持续收到分片时有效期按idle_timeout滑动延长，最长不超过申请后的max
+type UploadTTLConfig struct {
+	Default     time.Duration `yaml:"default" mapstructure:"default"`           // 固定有效期，观察样本不足时使用，默认24小时
+	Min         time.Duration `yaml:"min" mapstructure:"min"`                   // 有效期下限
+	Max         time.Duration `yaml:"max" mapstructure:"max"`                   // 有效期上限，也是滑动延长的上限，默认为固定有效期的3倍
+	Adaptive    bool          `yaml:"adaptive" mapstructure:"adaptive"`         // 是否按观察到的完成耗时调整有效期
+	Percentile  float64       `yaml:"percentile" mapstructure:"percentile"`     // 取完成耗时的分位数，默认0.95
+	Multiplier  float64       `yaml:"multiplier" mapstructure:"multiplier"`     // 分位数耗时的倍数，默认2
+	MinSamples  int           `yaml:"min_samples" mapstructure:"min_samples"`   // 开始调整所需的最少完成样本数
+	IdleTimeout time.Duration `yaml:"idle_timeout" mapstructure:"idle_timeout"` // 收到分片后有效期至少保留的时长，0表示不滑动延长
 }
 
 // LocalStorageConfig 本地存储配置
@@ -116,12 +292,63 @@ type OSSStorageConfig struct {
 	AutoSwitchSize  int64  `yaml:"auto_switch_size" mapstructure:"auto_switch_size"`
 }
 
+// S3StorageConfig S3兼容存储配置
+//
+// 作为文件存储后端时使用，适用于AWS S3、MinIO等兼容S3协议的服务
+type S3StorageConfig struct {
+	Endpoint        string `yaml:"endpoint" mapstructure:"endpoint"`                   // 服务地址，如 s3.amazonaws.com、minio.local:9000
+	Region          string `yaml:"region" mapstructure:"region"`                       // 区域，为空时使用us-east-1
+	Bucket          string `yaml:"bucket" mapstructure:"bucket"`                       // 存储桶名称
+	AccessKeyID     string `yaml:"access_key_id" mapstructure:"access_key_id"`         // 访问密钥ID
+	SecretAccessKey string `yaml:"secret_access_key" mapstructure:"secret_access_key"` // 访问密钥
+	Secure          bool   `yaml:"secure" mapstructure:"secure"`                       // 是否使用HTTPS
+	PathStyle       bool   `yaml:"path_style" mapstructure:"path_style"`               // 是否使用路径风格访问存储桶(MinIO通常需要开启)
+	PartSize        int64  `yaml:"part_size" mapstructure:"part_size"`                 // 分片上传的分片大小(字节)，超过该大小的文件使用分片上传
+}
+
+// ShareConfig 分享配置
+type ShareConfig struct {
+	MaxActiveShares           int           `yaml:"max_active_shares" mapstructure:"max_active_shares"`                       // 每个用户最多同时有效的分享数
+	MaxExpireDays             int           `yaml:"max_expire_days" mapstructure:"max_expire_days"`                           // 分享最长有效天数(0表示允许永久)
+	PasswordAttemptsPerWindow int           `yaml:"password_attempts_per_window" mapstructure:"password_attempts_per_window"` // 同一IP对同一分享每个窗口内允许的密码尝试次数
+	PasswordAttemptWindow     time.Duration `yaml:"password_attempt_window" mapstructure:"password_attempt_window"`           // 密码尝试计数窗口
+	PasswordLockThreshold     int           `yaml:"password_lock_threshold" mapstructure:"password_lock_threshold"`           // 累计失败多少次后临时锁定分享
+	PasswordLockDuration      time.Duration `yaml:"password_lock_duration" mapstructure:"password_lock_duration"`             // 分享密码锁定时长
+	MonthlyTransferLimit      int64         `yaml:"monthly_transfer_limit" mapstructure:"monthly_transfer_limit"`             // 每个分享每月下载流量上限(字节，0表示不限制)
+	StripImageLocation        bool          `yaml:"strip_image_location" mapstructure:"strip_image_location"`                 // 公开分享下载图片时默认去除GPS等位置信息
+	AbuseReportsPerWindow     int           `yaml:"abuse_reports_per_window" mapstructure:"abuse_reports_per_window"`         // 同一IP每个窗口内允许提交的举报数
+	AbuseReportWindow         time.Duration `yaml:"abuse_report_window" mapstructure:"abuse_report_window"`                   // 举报计数窗口
+	AbuseSuspendThreshold     int           `yaml:"abuse_suspend_threshold" mapstructure:"abuse_suspend_threshold"`           // 待审核举报来自多少个不同IP时自动暂停分享(0表示不自动暂停)
+	AbuseReportCaptcha        bool          `yaml:"abuse_report_captcha" mapstructure:"abuse_report_captcha"`                 // 举报是否需要人机验证(需同时启用 security.captcha)
+
+	// 按套餐(分享者资料中的plan)设置的每个分享每月下载流量上限，优先于系统设置和monthly_transfer_limit
+	MonthlyTransferLimitPlans map[string]int64 `yaml:"monthly_transfer_limit_plans" mapstructure:"monthly_transfer_limit_plans"`
+}
+
 // UserConfig 用户配置
 type UserConfig struct {
 	DefaultQuota int64          `yaml:"default_quota" mapstructure:"default_quota"`
 	MaxQuota     int64          `yaml:"max_quota" mapstructure:"max_quota"`
 	Avatar       AvatarConfig   `yaml:"avatar" mapstructure:"avatar"`
 	Password     PasswordConfig `yaml:"password" mapstructure:"password"`
+
+	Inactivity InactivityConfig `yaml:"inactivity" mapstructure:"inactivity"`
+}
+
+// InactivityConfig 长期未登录账户处置策略配置
+//
+// 账户超过 inactive_months 个月未登录时执行 action，超过 delete_after_months 个月时计划删除账户；
+// 每一步执行前按 warning_days 发送提醒邮件，用户重新登录即恢复
+type InactivityConfig struct {
+	Enabled           bool          `yaml:"enabled" mapstructure:"enabled"`                         // 是否启用
+	InactiveMonths    int           `yaml:"inactive_months" mapstructure:"inactive_months"`         // 未登录多少个月后执行处置
+	Action            string        `yaml:"action" mapstructure:"action"`                           // 处置方式：none不处置、downgrade降低存储配额、freeze冻结账户
+	DowngradedQuota   int64         `yaml:"downgraded_quota" mapstructure:"downgraded_quota"`       // downgrade时降低到的存储配额(字节)
+	DeleteAfterMonths int           `yaml:"delete_after_months" mapstructure:"delete_after_months"` // 未登录多少个月后计划删除账户，0表示不删除，需大于inactive_months
+	DeletionGrace     time.Duration `yaml:"deletion_grace" mapstructure:"deletion_grace"`           // 计划删除后可登录恢复的期限
+	WarningDays       []int         `yaml:"warning_days" mapstructure:"warning_days"`               // 每一步执行前多少天发送提醒邮件
+	ExemptRoles       []string      `yaml:"exempt_roles" mapstructure:"exempt_roles"`               // 不受策略影响的角色
+	LoginURL          string        `yaml:"login_url" mapstructure:"login_url"`                     // 提醒邮件中的登录页面地址
 }
 
 // AvatarConfig 头像配置
@@ -144,9 +371,41 @@ type PasswordConfig struct {
 
 // EmailConfig 邮件配置
 type EmailConfig struct {
-	SMTP       SMTPConfig       `yaml:"smtp" mapstructure:"smtp"`
-	Templates  TemplatesConfig  `yaml:"templates" mapstructure:"templates"`
-	VerifyCode VerifyCodeConfig `yaml:"verify_code" mapstructure:"verify_code"`
+	SMTP            SMTPConfig       `yaml:"smtp" mapstructure:"smtp"`
+	Templates       TemplatesConfig  `yaml:"templates" mapstructure:"templates"`
+	TemplateDir     string           `yaml:"template_dir" mapstructure:"template_dir"`         // 模板目录，按 <语言>/<模板名称>.subject|.html|.txt 覆盖内置模板，为空时只使用内置模板
+	DefaultLanguage string           `yaml:"default_language" mapstructure:"default_language"` // 收件人没有语言偏好时使用的模板语言，默认zh-CN
+	VerifyCode      VerifyCodeConfig `yaml:"verify_code" mapstructure:"verify_code"`
+	DeadLetter      DeadLetterConfig `yaml:"dead_letter" mapstructure:"dead_letter"`
+	Routing         EmailRouting     `yaml:"routing" mapstructure:"routing"`
+}
+
+// EmailRouting 按收件人域名选择发送线路的配置
+//
+// 国内邮箱和海外邮箱的送达率差异较大，可以为不同地区的收件人配置独立的SMTP服务器或IP池；
+// 未匹配任何线路的收件人使用 smtp 配置发送
+type EmailRouting struct {
+	Routes           []EmailRoute  `yaml:"routes" mapstructure:"routes"`
+	FailureThreshold float64       `yaml:"failure_threshold" mapstructure:"failure_threshold"` // 统计窗口内失败率达到该值时降级线路，默认0.5
+	MinSamples       int           `yaml:"min_samples" mapstructure:"min_samples"`             // 判断降级的最少发送次数，默认20
+	Window           time.Duration `yaml:"window" mapstructure:"window"`                       // 统计窗口，默认15分钟
+	DemoteDuration   time.Duration `yaml:"demote_duration" mapstructure:"demote_duration"`     // 降级时长，期间改用默认线路发送，默认10分钟
+}
+
+// EmailRoute 邮件发送线路
+type EmailRoute struct {
+	Name    string     `yaml:"name" mapstructure:"name"`       // 线路名称，不能为default
+	Domains []string   `yaml:"domains" mapstructure:"domains"` // 收件人域名，qq.com 同时匹配子域名，.cn 按后缀匹配
+	SMTP    SMTPConfig `yaml:"smtp" mapstructure:"smtp"`       // 发件人为空时使用默认发件人
+}
+
+// DeadLetterConfig 邮件死信队列配置
+//
+// 达到最大重试次数仍发送失败的邮件进入死信队列，Redis可用时保存在Redis中，重启后不丢失
+type DeadLetterConfig struct {
+	WarningThreshold  int64         `yaml:"warning_threshold" mapstructure:"warning_threshold"`   // 死信数达到该值时记录警告日志，0表示不告警
+	CriticalThreshold int64         `yaml:"critical_threshold" mapstructure:"critical_threshold"` // 死信数达到该值时记录错误日志，0表示不告警
+	AlertCooldown     time.Duration `yaml:"alert_cooldown" mapstructure:"alert_cooldown"`         // 同一级别告警的最短间隔，默认15分钟
 }
 
 // SMTPConfig SMTP配置
@@ -175,33 +434,131 @@ type VerifyCodeConfig struct {
 
 // SecurityConfig 安全配置
 type SecurityConfig struct {
-	CORS      CORSConfig      `yaml:"cors" mapstructure:"cors"`
-	RateLimit RateLimitConfig `yaml:"rate_limit" mapstructure:"rate_limit"`
-	Antivirus AntivirusConfig `yaml:"antivirus" mapstructure:"antivirus"`
+	CORS       CORSConfig       `yaml:"cors" mapstructure:"cors"`
+	Headers    HeadersConfig    `yaml:"headers" mapstructure:"headers"`
+	RateLimit  RateLimitConfig  `yaml:"rate_limit" mapstructure:"rate_limit"`
+	Antivirus  AntivirusConfig  `yaml:"antivirus" mapstructure:"antivirus"`
+	Encryption EncryptionConfig `yaml:"encryption" mapstructure:"encryption"`
+	Profanity  ProfanityConfig  `yaml:"profanity" mapstructure:"profanity"`
+	Captcha    CaptchaConfig    `yaml:"captcha" mapstructure:"captcha"`
+	Moderation ModerationConfig `yaml:"moderation" mapstructure:"moderation"`
+
+	ForcedPasswordReset ForcedPasswordResetConfig `yaml:"forced_password_reset" mapstructure:"forced_password_reset"`
+	Impersonation       ImpersonationConfig       `yaml:"impersonation" mapstructure:"impersonation"`
+	APIKeys             APIKeyConfig              `yaml:"api_keys" mapstructure:"api_keys"`
+}
+
+// APIKeyConfig 用户API密钥配置
+//
+// 用户在 /api/v1/users/me/api-keys 创建密钥，脚本和第三方工具通过 X-API-Key 请求头访问接口；
+// 每个密钥单独限流，不占用登录用户的限流额度
+type APIKeyConfig struct {
+	Enabled           bool          `yaml:"enabled" mapstructure:"enabled"`                         // 是否启用API密钥
+	MaxPerUser        int           `yaml:"max_per_user" mapstructure:"max_per_user"`               // 每个用户最多持有的密钥数
+	MaxTTL            time.Duration `yaml:"max_ttl" mapstructure:"max_ttl"`                         // 密钥最长有效期，为0时允许创建不过期的密钥
+	RequestsPerMinute int           `yaml:"requests_per_minute" mapstructure:"requests_per_minute"` // 每个密钥每分钟请求数，为0表示不限制
+	Burst             int           `yaml:"burst" mapstructure:"burst"`                             // 每个密钥的突发容量，为0时等于每分钟请求数
+}
+
+// ImpersonationConfig 管理员模拟用户登录配置
+type ImpersonationConfig struct {
+	DefaultTTL    time.Duration `yaml:"default_ttl" mapstructure:"default_ttl"`         // 未指定时长时模拟登录令牌的有效期
+	MaxTTL        time.Duration `yaml:"max_ttl" mapstructure:"max_ttl"`                 // 模拟登录令牌的最长有效期
+	ForceReadOnly bool          `yaml:"force_read_only" mapstructure:"force_read_only"` // 是否强制只读模式，开启后忽略请求中的只读选项
+}
+
+// ForcedPasswordResetConfig 管理员批量强制重置密码配置
+type ForcedPasswordResetConfig struct {
+	ResetURL      string        `yaml:"reset_url" mapstructure:"reset_url"`           // 邮件中的重置密码页面地址，为空时只提示使用忘记密码
+	BatchSize     int           `yaml:"batch_size" mapstructure:"batch_size"`         // 每批发送的重置邮件数
+	BatchInterval time.Duration `yaml:"batch_interval" mapstructure:"batch_interval"` // 批次间隔，避免占满邮件队列
+	MaxUsers      int           `yaml:"max_users" mapstructure:"max_users"`           // 单次任务最多包含的用户数
+}
+
+// ProfanityConfig 敏感词过滤配置(用于用户名和团队名称)
+type ProfanityConfig struct {
+	Enabled bool     `yaml:"enabled" mapstructure:"enabled"` // 是否启用敏感词过滤
+	Words   []string `yaml:"words" mapstructure:"words"`     // 敏感词列表(匹配时忽略大小写、标点和同形字替换)
+}
+
+// EncryptionConfig 敏感字段加密配置
+type EncryptionConfig struct {
+	ActiveKey string   `yaml:"active_key" mapstructure:"active_key"` // 当前用于加密的密钥版本，为空表示不加密
+	Keys      []string `yaml:"keys" mapstructure:"keys"`             // 密钥列表，格式为 版本:Base64编码的32字节密钥
 }
 
 // CORSConfig CORS配置
+//
+// 部署在其他域名的前端需要把页面来源加入 allow_origins，未配置的项使用内置默认值
 type CORSConfig struct {
-	AllowOrigins     []string `yaml:"allow_origins" mapstructure:"allow_origins"`
-	AllowMethods     []string `yaml:"allow_methods" mapstructure:"allow_methods"`
-	AllowHeaders     []string `yaml:"allow_headers" mapstructure:"allow_headers"`
-	ExposeHeaders    []string `yaml:"expose_headers" mapstructure:"expose_headers"`
-	AllowCredentials bool     `yaml:"allow_credentials" mapstructure:"allow_credentials"`
-	MaxAge           int      `yaml:"max_age" mapstructure:"max_age"`
+	AllowOrigins     []string `yaml:"allow_origins" mapstructure:"allow_origins"`         // 允许的页面来源，支持 *.example.com，为空时调试模式允许所有来源、生产环境只允许官方域名
+	AllowMethods     []string `yaml:"allow_methods" mapstructure:"allow_methods"`         // 允许的请求方法，为空时使用默认值
+	AllowHeaders     []string `yaml:"allow_headers" mapstructure:"allow_headers"`         // 允许的请求头，为空时使用默认值
+	ExposeHeaders    []string `yaml:"expose_headers" mapstructure:"expose_headers"`       // 允许前端读取的响应头，为空时使用默认值
+	AllowCredentials *bool    `yaml:"allow_credentials" mapstructure:"allow_credentials"` // 是否允许携带凭证，未配置时允许
+	MaxAge           int      `yaml:"max_age" mapstructure:"max_age"`                     // 预检请求缓存秒数，为0时使用默认值
+}
+
+// HeadersConfig 安全响应头配置
+//
+// 启用后所有响应带有 nosniff、内容安全策略和 Referrer-Policy；预览和下载接口使用用户内容沙箱的策略
+type HeadersConfig struct {
+	Enabled               bool          `yaml:"enabled" mapstructure:"enabled"`                                 // 是否添加安全响应头
+	ContentSecurityPolicy string        `yaml:"content_security_policy" mapstructure:"content_security_policy"` // 内容安全策略，为空时不允许加载任何资源和被嵌入页面
+	FrameOptions          string        `yaml:"frame_options" mapstructure:"frame_options"`                     // X-Frame-Options，如DENY，为空时不设置
+	ReferrerPolicy        string        `yaml:"referrer_policy" mapstructure:"referrer_policy"`                 // Referrer-Policy，为空时为no-referrer
+	HSTSMaxAge            time.Duration `yaml:"hsts_max_age" mapstructure:"hsts_max_age"`                       // HSTS有效期，只在HTTPS请求(含反向代理X-Forwarded-Proto为https)上设置，为0时不设置
+	HSTSIncludeSubdomains bool          `yaml:"hsts_include_subdomains" mapstructure:"hsts_include_subdomains"` // HSTS是否包含子域名
+	HSTSPreload           bool          `yaml:"hsts_preload" mapstructure:"hsts_preload"`                       // HSTS是否带preload指令
 }
 
 // RateLimitConfig 限流配置
 type RateLimitConfig struct {
 	Enabled           bool `yaml:"enabled" mapstructure:"enabled"`
-	RequestsPerMinute int  `yaml:"requests_per_minute" mapstructure:"requests_per_minute"`
-	Burst             int  `yaml:"burst" mapstructure:"burst"`
+	RequestsPerMinute int  `yaml:"requests_per_minute" mapstructure:"requests_per_minute"` // 每个IP每分钟请求数，为0表示不限制
+	Burst             int  `yaml:"burst" mapstructure:"burst"`                             // 每个IP的突发容量，为0时等于每分钟请求数
+
+	UserRequestsPerMinute int                    `yaml:"user_requests_per_minute" mapstructure:"user_requests_per_minute"` // 每个登录用户每分钟请求数，为0时登录请求也按IP限流
+	UserBurst             int                    `yaml:"user_burst" mapstructure:"user_burst"`                             // 每个登录用户的突发容量
+	Routes                []RouteRateLimitConfig `yaml:"routes" mapstructure:"routes"`                                     // 单个接口的限流
+}
+
+// RouteRateLimitConfig 单个接口的限流配置，登录请求按用户计数，未登录请求按IP计数
+type RouteRateLimitConfig struct {
+	Method            string `yaml:"method" mapstructure:"method"`                           // 请求方法，为空匹配全部方法
+	Path              string `yaml:"path" mapstructure:"path"`                               // 路由模板，如 /api/v1/auth/login
+	RequestsPerMinute int    `yaml:"requests_per_minute" mapstructure:"requests_per_minute"` // 每分钟请求数
+	Burst             int    `yaml:"burst" mapstructure:"burst"`                             // 突发容量，为0时等于每分钟请求数
 }
 
 // AntivirusConfig 病毒扫描配置
 type AntivirusConfig struct {
 	Enabled      bool          `yaml:"enabled" mapstructure:"enabled"`
-	ClamAVSocket string        `yaml:"clamav_socket" mapstructure:"clamav_socket"`
+	ClamAVSocket string        `yaml:"clamav_socket" mapstructure:"clamav_socket"` // clamd地址：unix socket路径或 tcp://host:port
 	ScanTimeout  time.Duration `yaml:"scan_timeout" mapstructure:"scan_timeout"`
+	MaxFileSize  int64         `yaml:"max_file_size" mapstructure:"max_file_size"` // 扫描的文件大小上限(字节)，超过时标记为未扫描
+}
+
+// CaptchaConfig 人机验证配置，兼容 reCAPTCHA、hCaptcha 和 Cloudflare Turnstile 的 siteverify 接口
+type CaptchaConfig struct {
+	Enabled   bool          `yaml:"enabled" mapstructure:"enabled"`
+	VerifyURL string        `yaml:"verify_url" mapstructure:"verify_url"` // 服务端校验地址
+	Secret    string        `yaml:"secret" mapstructure:"secret"`         // 服务端密钥
+	Timeout   time.Duration `yaml:"timeout" mapstructure:"timeout"`
+}
+
+// ModerationConfig 公开分享图片的内容审核配置
+//
+// 审核接口接收图片内容，返回各标签(如 nsfw、violence)的置信度，
+// 最高置信度达到阈值时标记待复核或禁止公开分享
+type ModerationConfig struct {
+	Enabled        bool          `yaml:"enabled" mapstructure:"enabled"`
+	Endpoint       string        `yaml:"endpoint" mapstructure:"endpoint"` // 审核接口地址
+	APIKey         string        `yaml:"api_key" mapstructure:"api_key"`   // 审核接口密钥，以 Bearer 令牌发送
+	Timeout        time.Duration `yaml:"timeout" mapstructure:"timeout"`
+	MaxFileSize    int64         `yaml:"max_file_size" mapstructure:"max_file_size"`     // 审核的图片大小上限(字节)，超过时不审核
+	FlagThreshold  float64       `yaml:"flag_threshold" mapstructure:"flag_threshold"`   // 置信度达到该值时允许分享但进入复核队列
+	BlockThreshold float64       `yaml:"block_threshold" mapstructure:"block_threshold"` // 置信度达到该值时禁止公开分享
 }
 
 // LogConfig 日志配置
@@ -244,6 +601,18 @@ type RedisStreamConfig struct {
 	ConsumerGroup string            `yaml:"consumer_group" mapstructure:"consumer_group"`
 }
 
+// JobsConfig 后台任务队列配置
+type JobsConfig struct {
+	Pools map[string]JobPoolConfig `yaml:"pools" mapstructure:"pools"` // 按任务类型(如thumbnail、transcode)配置的工作池，未配置的类型使用默认值
+}
+
+// JobPoolConfig 单个任务类型的工作池配置
+type JobPoolConfig struct {
+	Concurrency      int `yaml:"concurrency" mapstructure:"concurrency"`             // 同时执行的任务数，默认1
+	BatchConcurrency int `yaml:"batch_concurrency" mapstructure:"batch_concurrency"` // 批量回填任务最多占用的并发数，默认比concurrency少1，为预览任务保留空位
+	QueueSize        int `yaml:"queue_size" mapstructure:"queue_size"`               // 排队任务上限，超过时拒绝新任务，默认256
+}
+
 // WebSocketConfig WebSocket配置
 type WebSocketConfig struct {
 	Enabled          bool          `yaml:"enabled" mapstructure:"enabled"`
@@ -264,6 +633,109 @@ type MonitoringConfig struct {
 	Metrics MetricsConfig `yaml:"metrics" mapstructure:"metrics"`
 	Health  HealthConfig  `yaml:"health" mapstructure:"health"`
 	PProf   PProfConfig   `yaml:"pprof" mapstructure:"pprof"`
+
+	APIUsage APIUsageConfig `yaml:"api_usage" mapstructure:"api_usage"`
+	Tracing  TracingConfig  `yaml:"tracing" mapstructure:"tracing"`
+}
+
+// TracingConfig 分布式追踪配置(OpenTelemetry)
+//
+// 启用后为每个HTTP请求创建span，并在服务、数据库和Redis调用中延续请求的追踪链路。
+// Jaeger 1.35及以上版本直接接收OTLP，exporter为jaeger时使用Jaeger的OTLP端口；
+// 接收端需要的认证头通过标准环境变量 OTEL_EXPORTER_OTLP_HEADERS 设置
+type TracingConfig struct {
+	Enabled     bool          `yaml:"enabled" mapstructure:"enabled"`           // 是否启用追踪
+	Exporter    string        `yaml:"exporter" mapstructure:"exporter"`         // 导出器：otlp(默认)、jaeger、stdout(输出到标准输出，用于本地调试)
+	Protocol    string        `yaml:"protocol" mapstructure:"protocol"`         // OTLP传输协议：http(默认)或grpc
+	Endpoint    string        `yaml:"endpoint" mapstructure:"endpoint"`         // 接收端地址host:port，默认 localhost:4318(http)或 localhost:4317(grpc)
+	Insecure    bool          `yaml:"insecure" mapstructure:"insecure"`         // 不使用TLS连接接收端
+	SampleRatio float64       `yaml:"sample_ratio" mapstructure:"sample_ratio"` // 新链路的采样比例(0-1]，默认1；上游已决定是否采样的请求沿用上游的决定
+	ServiceName string        `yaml:"service_name" mapstructure:"service_name"` // 上报的服务名，默认使用app.name
+	Timeout     time.Duration `yaml:"timeout" mapstructure:"timeout"`           // 单次导出超时，默认10秒
+}
+
+// APIUsageConfig 按用户统计API用量配置
+type APIUsageConfig struct {
+	Enabled       bool          `yaml:"enabled" mapstructure:"enabled"`                 // 是否统计API用量
+	FlushInterval time.Duration `yaml:"flush_interval" mapstructure:"flush_interval"`   // 内存计数累加写入日汇总表的间隔，默认1分钟
+	MeteredRoles  []string      `yaml:"metered_roles" mapstructure:"metered_roles"`     // 计量套餐对应的角色，持有任一角色的用户可查看用量报表；为空时所有用户可查看
+	MaxReportDays int           `yaml:"max_report_days" mapstructure:"max_report_days"` // 单次查询或导出的最大天数，默认366
+	TopEndpoints  int           `yaml:"top_endpoints" mapstructure:"top_endpoints"`     // 报表中列出的调用最多的接口数，默认10
+}
+
+// MaintenanceConfig 定期维护任务配置
+type MaintenanceConfig struct {
+	Enabled   bool          `yaml:"enabled" mapstructure:"enabled"`       // 是否启用定期清理(过期验证码、会话、分享和进程内计数)
+	Interval  time.Duration `yaml:"interval" mapstructure:"interval"`     // 每个任务的执行间隔，默认1小时
+	Jitter    float64       `yaml:"jitter" mapstructure:"jitter"`         // 间隔随机浮动比例(0-1)，多实例错开执行，默认0.1
+	BatchSize int           `yaml:"batch_size" mapstructure:"batch_size"` // 每批删除或更新的记录数，默认500
+	// 按任务名称设置执行间隔(如 verification_codes: 15m)，未设置的任务使用interval
+	Tasks map[string]time.Duration `yaml:"tasks" mapstructure:"tasks"`
+}
+
+// BackupConfig 数据库元数据备份配置
+type BackupConfig struct {
+	Enabled   bool          `yaml:"enabled" mapstructure:"enabled"`       // 是否启用定期备份(由维护任务调度器执行，需同时启用maintenance)
+	Interval  time.Duration `yaml:"interval" mapstructure:"interval"`     // 备份间隔，默认24小时
+	Tables    []string      `yaml:"tables" mapstructure:"tables"`         // 备份的表，默认 users、files、file_shares
+	Prefix    string        `yaml:"prefix" mapstructure:"prefix"`         // 备份在存储后端中的路径前缀，默认 backups/metadata
+	Retention int           `yaml:"retention" mapstructure:"retention"`   // 保留最近的备份数，默认7
+	ActiveKey string        `yaml:"active_key" mapstructure:"active_key"` // 当前用于加密备份的密钥版本
+	Keys      []string      `yaml:"keys" mapstructure:"keys"`             // 密钥列表，格式为 版本:Base64编码的32字节密钥，旧版本用于恢复历史备份
+}
+
+// AutomationConfig 文件夹自动化规则配置
+//
+// 文件上传到设置了规则的文件夹后，在后台任务(jobs.pools.automation)中按条件调用Webhook、添加标签或移动文件
+type AutomationConfig struct {
+	Enabled              bool          `yaml:"enabled" mapstructure:"enabled"`                               // 是否启用文件夹自动化规则
+	MaxRulesPerUser      int           `yaml:"max_rules_per_user" mapstructure:"max_rules_per_user"`         // 每个用户最多的规则数，默认50
+	WebhookTimeout       time.Duration `yaml:"webhook_timeout" mapstructure:"webhook_timeout"`               // Webhook请求超时，默认10秒
+	AllowPrivateWebhooks bool          `yaml:"allow_private_webhooks" mapstructure:"allow_private_webhooks"` // 是否允许Webhook访问内网和回环地址，默认禁止
+	LogRetention         time.Duration `yaml:"log_retention" mapstructure:"log_retention"`                   // 执行日志保留时长，默认30天，由维护任务清理
+}
+
+// TeamConfig 团队配置
+//
+// 删除的团队先归档，恢复期限内所有者可以恢复，过期后由维护任务彻底删除
+type TeamConfig struct {
+	RestoreWindow time.Duration `yaml:"restore_window" mapstructure:"restore_window"` // 删除后可恢复的时长，默认30天
+}
+
+// SSOConfig 企业单点登录(OIDC)配置
+//
+// 身份提供方按租户在管理后台配置，这里只包含全局参数
+type SSOConfig struct {
+	Enabled     bool          `yaml:"enabled" mapstructure:"enabled"`           // 是否启用企业单点登录
+	RedirectURL string        `yaml:"redirect_url" mapstructure:"redirect_url"` // 前端回调页面地址，需在身份提供方登记
+	StateTTL    time.Duration `yaml:"state_ttl" mapstructure:"state_ttl"`       // 登录请求(state)有效期，默认10分钟
+	HTTPTimeout time.Duration `yaml:"http_timeout" mapstructure:"http_timeout"` // 访问身份提供方的超时，默认10秒
+}
+
+// LDAPConfig 企业目录(LDAP)认证配置
+//
+// 启用后密码登录先用服务账号在目录中搜索用户，再以用户DN和密码绑定验证；
+// 目录中没有该用户或目录不可用时回退到本地账户认证。首次登录时可以即时开通账户，
+// 用户所在的组按映射授予角色并同步为团队成员。组DN比较不区分大小写
+type LDAPConfig struct {
+	Enabled            bool              `yaml:"enabled" mapstructure:"enabled"`                           // 是否启用LDAP认证
+	URL                string            `yaml:"url" mapstructure:"url"`                                   // 目录服务器地址，ldap://host:389 或 ldaps://host:636
+	StartTLS           bool              `yaml:"start_tls" mapstructure:"start_tls"`                       // ldap:// 连接是否升级为TLS
+	InsecureSkipVerify bool              `yaml:"insecure_skip_verify" mapstructure:"insecure_skip_verify"` // 跳过服务器证书校验，只用于测试环境
+	Timeout            time.Duration     `yaml:"timeout" mapstructure:"timeout"`                           // 连接和单个操作的超时，默认10秒
+	BindDN             string            `yaml:"bind_dn" mapstructure:"bind_dn"`                           // 搜索用户使用的服务账号DN，为空时匿名搜索
+	BindPassword       string            `yaml:"bind_password" mapstructure:"bind_password"`               // 服务账号密码
+	BaseDN             string            `yaml:"base_dn" mapstructure:"base_dn"`                           // 用户搜索的基准DN
+	UserFilter         string            `yaml:"user_filter" mapstructure:"user_filter"`                   // 用户搜索过滤器，{username}替换为转义后的登录名，默认(|(uid={username})(mail={username}))
+	EmailAttribute     string            `yaml:"email_attribute" mapstructure:"email_attribute"`           // 邮箱属性，默认mail
+	NameAttribute      string            `yaml:"name_attribute" mapstructure:"name_attribute"`             // 显示名称属性，默认displayName
+	GroupAttribute     string            `yaml:"group_attribute" mapstructure:"group_attribute"`           // 用户条目中所属组DN的属性，默认memberOf
+	RoleMappings       map[string]string `yaml:"role_mappings" mapstructure:"role_mappings"`               // 组DN到角色(user/moderator/admin)的映射
+	DefaultRole        string            `yaml:"default_role" mapstructure:"default_role"`                 // 没有组匹配时的角色，默认user
+	TeamMappings       map[string]uint   `yaml:"team_mappings" mapstructure:"team_mappings"`               // 组DN到团队ID的映射，登录时按组同步团队成员
+	JITEnabled         bool              `yaml:"jit_enabled" mapstructure:"jit_enabled"`                   // 目录用户首次登录时是否即时开通本地账户
+	Tenant             string            `yaml:"tenant" mapstructure:"tenant"`                             // 即时开通账户记录的租户标识，用于按租户评估特性开关
+	StorageQuota       int64             `yaml:"storage_quota" mapstructure:"storage_quota"`               // 即时开通账户的存储配额(字节)，默认10GB
 }
 
 // MetricsConfig 指标配置
@@ -297,12 +769,35 @@ type ThirdPartyConfig struct {
 	Geo GeoConfig `yaml:"geo" mapstructure:"geo"`
 }
 
-// SMSConfig 短信服务配置
+// SMSConfig 短信服务配置，用于发送手机验证码
 type SMSConfig struct {
-	Enabled   bool   `yaml:"enabled" mapstructure:"enabled"`
-	Provider  string `yaml:"provider" mapstructure:"provider"`
-	AppID     string `yaml:"app_id" mapstructure:"app_id"`
-	AppSecret string `yaml:"app_secret" mapstructure:"app_secret"`
+	Enabled            bool            `yaml:"enabled" mapstructure:"enabled"`
+	Provider           string          `yaml:"provider" mapstructure:"provider"`                         // 短信提供方：aliyun、twilio
+	DefaultCountryCode string          `yaml:"default_country_code" mapstructure:"default_country_code"` // 未带国家码的手机号使用的国家码，如86
+	HourlyLimit        int             `yaml:"hourly_limit" mapstructure:"hourly_limit"`                 // 同一手机号每小时最多发送的验证码条数，0使用默认值
+	Timeout            time.Duration   `yaml:"timeout" mapstructure:"timeout"`                           // 调用提供方接口的超时
+	Aliyun             AliyunSMSConfig `yaml:"aliyun" mapstructure:"aliyun"`
+	Twilio             TwilioSMSConfig `yaml:"twilio" mapstructure:"twilio"`
+}
+
+// AliyunSMSConfig 阿里云短信服务配置，验证码模板需包含 ${code} 变量
+type AliyunSMSConfig struct {
+	AccessKeyID     string `yaml:"access_key_id" mapstructure:"access_key_id"`
+	AccessKeySecret string `yaml:"access_key_secret" mapstructure:"access_key_secret"`
+	SignName        string `yaml:"sign_name" mapstructure:"sign_name"`         // 短信签名
+	TemplateCode    string `yaml:"template_code" mapstructure:"template_code"` // 验证码短信模板
+	Endpoint        string `yaml:"endpoint" mapstructure:"endpoint"`           // 接口地址，为空时使用公网地址
+	RegionID        string `yaml:"region_id" mapstructure:"region_id"`
+}
+
+// TwilioSMSConfig Twilio短信配置，From 和 MessagingServiceSID 至少配置一个
+type TwilioSMSConfig struct {
+	AccountSID          string `yaml:"account_sid" mapstructure:"account_sid"`
+	AuthToken           string `yaml:"auth_token" mapstructure:"auth_token"`
+	From                string `yaml:"from" mapstructure:"from"`                                   // 发送号码(E.164格式)
+	MessagingServiceSID string `yaml:"messaging_service_sid" mapstructure:"messaging_service_sid"` // 消息服务SID，配置后优先于发送号码
+	BaseURL             string `yaml:"base_url" mapstructure:"base_url"`                           // 接口地址，为空时使用 https://api.twilio.com
+	Body                string `yaml:"body" mapstructure:"body"`                                   // 短信内容模板，{code} 替换为验证码
 }
 
 // GeoConfig 地理位置服务配置
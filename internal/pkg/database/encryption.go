@@ -0,0 +1,212 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/config"
+	basemodels "cloudpan/internal/pkg/database/models"
+)
+
+// defaultReencryptBatchSize 重加密默认批次大小
+const defaultReencryptBatchSize = 500
+
+// EncryptedColumnSpec 加密列描述
+type EncryptedColumnSpec struct {
+	Table         string   // 表名
+	SubjectColumn string   // 加密主体列(为空表示不按主体派生密钥)
+	Columns       []string // 加密列
+}
+
+// SensitiveColumns 使用 encrypted 序列化器存储的敏感列，需与模型定义保持一致
+var SensitiveColumns = []EncryptedColumnSpec{
+	{Table: "users", SubjectColumn: "uuid", Columns: []string{"phone", "mfa_secret", "mfa_backup_codes"}},
+	{Table: "user_two_factors", SubjectColumn: "user_id", Columns: []string{"secret"}},
+	{Table: "sso_providers", SubjectColumn: "tenant", Columns: []string{"client_secret"}},
+}
+
+// ReencryptStats 重加密统计
+type ReencryptStats struct {
+	Table   string `json:"table"`   // 表名
+	Scanned int    `json:"scanned"` // 扫描的行数
+	Updated int    `json:"updated"` // 重加密的行数
+}
+
+// InitFieldEncryption 根据配置初始化敏感字段加密密钥环
+//
+// 未配置 active_key 时关闭加密(新数据按明文写入)，配置错误时返回错误阻止启动
+func InitFieldEncryption() error {
+	if config.AppConfig == nil {
+		return fmt.Errorf("配置未初始化")
+	}
+
+	keyring, err := NewFieldKeyringFromConfig(config.AppConfig.Security.Encryption)
+	if err != nil {
+		return err
+	}
+	if keyring == nil {
+		log.Println("Field encryption is disabled: security.encryption.active_key not configured")
+	}
+	basemodels.SetFieldKeyring(keyring)
+	return nil
+}
+
+// NewFieldKeyringFromConfig 根据配置创建密钥环，未启用加密时返回nil
+func NewFieldKeyringFromConfig(cfg config.EncryptionConfig) (*basemodels.FieldKeyring, error) {
+	if cfg.ActiveKey == "" {
+		return nil, nil
+	}
+
+	keys, err := basemodels.ParseFieldKeys(cfg.Keys)
+	if err != nil {
+		return nil, fmt.Errorf("解析字段加密密钥失败: %w", err)
+	}
+	keyring, err := basemodels.NewFieldKeyring(cfg.ActiveKey, keys)
+	if err != nil {
+		return nil, fmt.Errorf("初始化字段加密密钥环失败: %w", err)
+	}
+	return keyring, nil
+}
+
+// ReencryptSensitiveColumns 使用当前密钥版本重加密全部敏感列
+//
+// 用于密钥轮换(旧版本密钥加密的数据)和启用加密前遗留的明文数据，可重复执行
+func ReencryptSensitiveColumns(db *gorm.DB, batchSize int) ([]ReencryptStats, error) {
+	keyring := basemodels.GetFieldKeyring()
+	if keyring == nil {
+		return nil, basemodels.ErrFieldKeyringNotConfigured
+	}
+
+	results := make([]ReencryptStats, 0, len(SensitiveColumns))
+	for _, spec := range SensitiveColumns {
+		stats, err := ReencryptColumns(db, keyring, spec, batchSize)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, *stats)
+	}
+	return results, nil
+}
+
+// ReencryptColumns 按主键分批重加密指定表的加密列
+//
+// 直接读写原始列值，绕过模型钩子和序列化器，软删除的记录同样会被处理
+func ReencryptColumns(db *gorm.DB, keyring *basemodels.FieldKeyring, spec EncryptedColumnSpec, batchSize int) (*ReencryptStats, error) {
+	if batchSize <= 0 {
+		batchSize = defaultReencryptBatchSize
+	}
+
+	selectColumns := []string{"id"}
+	if spec.SubjectColumn != "" {
+		selectColumns = append(selectColumns, spec.SubjectColumn)
+	}
+	selectColumns = append(selectColumns, spec.Columns...)
+
+	stats := &ReencryptStats{Table: spec.Table}
+	var lastID uint
+	for {
+		rows, err := db.Table(spec.Table).
+			Select(selectColumns).
+			Where("id > ?", lastID).
+			Order("id ASC").
+			Limit(batchSize).
+			Rows()
+		if err != nil {
+			return stats, fmt.Errorf("读取 %s 失败: %w", spec.Table, err)
+		}
+
+		batch, err := scanEncryptedRows(rows, spec)
+		if err != nil {
+			return stats, fmt.Errorf("读取 %s 失败: %w", spec.Table, err)
+		}
+		if len(batch) == 0 {
+			return stats, nil
+		}
+
+		for _, row := range batch {
+			lastID = row.id
+			stats.Scanned++
+
+			updates, err := reencryptRow(keyring, spec, row)
+			if err != nil {
+				return stats, fmt.Errorf("重加密 %s#%d 失败: %w", spec.Table, row.id, err)
+			}
+			if len(updates) == 0 {
+				continue
+			}
+			if err := db.Table(spec.Table).Where("id = ?", row.id).UpdateColumns(updates).Error; err != nil {
+				return stats, fmt.Errorf("更新 %s#%d 失败: %w", spec.Table, row.id, err)
+			}
+			stats.Updated++
+		}
+
+		if len(batch) < batchSize {
+			return stats, nil
+		}
+	}
+}
+
+// encryptedRow 重加密时读取的一行数据
+type encryptedRow struct {
+	id      uint
+	subject string
+	values  []sql.NullString
+}
+
+// scanEncryptedRows 读取一批待重加密的行
+func scanEncryptedRows(rows *sql.Rows, spec EncryptedColumnSpec) ([]encryptedRow, error) {
+	defer rows.Close()
+
+	var batch []encryptedRow
+	for rows.Next() {
+		row := encryptedRow{values: make([]sql.NullString, len(spec.Columns))}
+		var subject sql.NullString
+
+		dest := []interface{}{&row.id}
+		if spec.SubjectColumn != "" {
+			dest = append(dest, &subject)
+		}
+		for i := range row.values {
+			dest = append(dest, &row.values[i])
+		}
+
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		row.subject = subject.String
+		batch = append(batch, row)
+	}
+	return batch, rows.Err()
+}
+
+// reencryptRow 计算一行中需要重加密的列
+func reencryptRow(keyring *basemodels.FieldKeyring, spec EncryptedColumnSpec, row encryptedRow) (map[string]interface{}, error) {
+	updates := make(map[string]interface{})
+	for i, column := range spec.Columns {
+		value := row.values[i]
+		if !value.Valid || !keyring.NeedsReencrypt(value.String) {
+			continue
+		}
+
+		plaintext, err := keyring.Decrypt(value.String)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", column, err)
+		}
+
+		// 已加密的值保留原加密主体，保证与写入时派生的密钥一致
+		subject := row.subject
+		if basemodels.IsEncryptedValue(value.String) {
+			subject = basemodels.EncryptedValueSubject(value.String)
+		}
+
+		ciphertext, err := keyring.Encrypt(plaintext, subject)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", column, err)
+		}
+		updates[column] = ciphertext
+	}
+	return updates, nil
+}
@@ -0,0 +1,190 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	_ "modernc.org/sqlite" // 使用纯Go的SQLite驱动
+
+	"cloudpan/internal/pkg/config"
+	basemodels "cloudpan/internal/pkg/database/models"
+)
+
+// encryptedTestUser 字段加密测试模型
+type encryptedTestUser struct {
+	ID        uint    `gorm:"primarykey"`
+	UUID      string  `gorm:"type:varchar(36)"`
+	Phone     *string `gorm:"type:varchar(512);serializer:encrypted"`
+	MFASecret string  `gorm:"type:varchar(512);serializer:encrypted"`
+}
+
+func (u *encryptedTestUser) EncryptionSubject() string {
+	return u.UUID
+}
+
+var encryptedTestSpec = EncryptedColumnSpec{
+	Table:         "encrypted_test_users",
+	SubjectColumn: "uuid",
+	Columns:       []string{"phone", "mfa_secret"},
+}
+
+func testFieldKey(b byte) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(b), 32)))
+}
+
+func setupEncryptionTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	sqlDB, err := sql.Open("sqlite", ":memory:")
+	require.NoError(t, err)
+	db, err := gorm.Open(sqlite.Dialector{Conn: sqlDB}, &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&encryptedTestUser{}))
+
+	t.Cleanup(func() { basemodels.SetFieldKeyring(nil) })
+	return db
+}
+
+func rawColumn(t *testing.T, db *gorm.DB, id uint, column string) string {
+	t.Helper()
+	var value sql.NullString
+	require.NoError(t, db.Table(encryptedTestSpec.Table).Select(column).Where("id = ?", id).Row().Scan(&value))
+	return value.String
+}
+
+func TestEncryptedSerializer_RoundTrip(t *testing.T) {
+	db := setupEncryptionTestDB(t)
+	keyring, err := basemodels.NewFieldKeyring("v1", map[string]string{"v1": testFieldKey('a')})
+	require.NoError(t, err)
+	basemodels.SetFieldKeyring(keyring)
+
+	phone := "13800138000"
+	user := &encryptedTestUser{UUID: "user-a", Phone: &phone, MFASecret: "JBSWY3DPEHPK3PXP"}
+	require.NoError(t, db.Create(user).Error)
+
+	raw := rawColumn(t, db, user.ID, "phone")
+	assert.True(t, strings.HasPrefix(raw, "enc:v1:user-a:"))
+	assert.NotContains(t, raw, phone)
+
+	var loaded encryptedTestUser
+	require.NoError(t, db.First(&loaded, user.ID).Error)
+	require.NotNil(t, loaded.Phone)
+	assert.Equal(t, phone, *loaded.Phone)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", loaded.MFASecret)
+
+	// 加密主体不同，相同明文的密文不可互换
+	other := &encryptedTestUser{UUID: "user-b", Phone: &phone}
+	require.NoError(t, db.Create(other).Error)
+	tampered := strings.Replace(rawColumn(t, db, other.ID, "phone"), ":user-b:", ":user-a:", 1)
+	_, err = keyring.Decrypt(tampered)
+	assert.Error(t, err)
+}
+
+func TestEncryptedSerializer_NilValueAndLegacyPlaintext(t *testing.T) {
+	db := setupEncryptionTestDB(t)
+
+	// 未配置密钥环时按明文写入
+	phone := "13900139000"
+	legacy := &encryptedTestUser{UUID: "legacy", Phone: &phone}
+	require.NoError(t, db.Create(legacy).Error)
+	assert.Equal(t, phone, rawColumn(t, db, legacy.ID, "phone"))
+
+	keyring, err := basemodels.NewFieldKeyring("v1", map[string]string{"v1": testFieldKey('a')})
+	require.NoError(t, err)
+	basemodels.SetFieldKeyring(keyring)
+
+	var loaded encryptedTestUser
+	require.NoError(t, db.First(&loaded, legacy.ID).Error)
+	require.NotNil(t, loaded.Phone)
+	assert.Equal(t, phone, *loaded.Phone)
+
+	empty := &encryptedTestUser{UUID: "empty"}
+	require.NoError(t, db.Create(empty).Error)
+	var loadedEmpty encryptedTestUser
+	require.NoError(t, db.First(&loadedEmpty, empty.ID).Error)
+	assert.Nil(t, loadedEmpty.Phone)
+}
+
+func TestReencryptColumns_RotatesKeys(t *testing.T) {
+	db := setupEncryptionTestDB(t)
+
+	// 遗留明文
+	plainPhone := "13700137000"
+	legacy := &encryptedTestUser{UUID: "legacy", Phone: &plainPhone, MFASecret: "legacy-secret"}
+	require.NoError(t, db.Create(legacy).Error)
+
+	// 旧版本密钥加密的数据
+	oldKeyring, err := basemodels.NewFieldKeyring("v1", map[string]string{"v1": testFieldKey('a')})
+	require.NoError(t, err)
+	basemodels.SetFieldKeyring(oldKeyring)
+	phone := "13600136000"
+	user := &encryptedTestUser{UUID: "user-a", Phone: &phone, MFASecret: "secret"}
+	require.NoError(t, db.Create(user).Error)
+
+	newKeyring, err := basemodels.NewFieldKeyring("v2", map[string]string{
+		"v1": testFieldKey('a'),
+		"v2": testFieldKey('b'),
+	})
+	require.NoError(t, err)
+	basemodels.SetFieldKeyring(newKeyring)
+
+	stats, err := ReencryptColumns(db, newKeyring, encryptedTestSpec, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stats.Scanned)
+	assert.Equal(t, 2, stats.Updated)
+
+	assert.True(t, strings.HasPrefix(rawColumn(t, db, user.ID, "phone"), "enc:v2:user-a:"))
+	assert.True(t, strings.HasPrefix(rawColumn(t, db, legacy.ID, "mfa_secret"), "enc:v2:legacy:"))
+
+	var loaded encryptedTestUser
+	require.NoError(t, db.First(&loaded, user.ID).Error)
+	assert.Equal(t, phone, *loaded.Phone)
+	assert.Equal(t, "secret", loaded.MFASecret)
+
+	// 重复执行不再更新
+	stats, err = ReencryptColumns(db, newKeyring, encryptedTestSpec, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 0, stats.Updated)
+
+	// 移除旧密钥后仍可解密
+	v2Only, err := basemodels.NewFieldKeyring("v2", map[string]string{"v2": testFieldKey('b')})
+	require.NoError(t, err)
+	basemodels.SetFieldKeyring(v2Only)
+	var loadedLegacy encryptedTestUser
+	require.NoError(t, db.First(&loadedLegacy, legacy.ID).Error)
+	assert.Equal(t, plainPhone, *loadedLegacy.Phone)
+}
+
+func TestNewFieldKeyringFromConfig(t *testing.T) {
+	keyring, err := NewFieldKeyringFromConfig(config.EncryptionConfig{})
+	require.NoError(t, err)
+	assert.Nil(t, keyring)
+
+	keyring, err = NewFieldKeyringFromConfig(config.EncryptionConfig{
+		ActiveKey: "v2",
+		Keys:      []string{"v1:" + testFieldKey('a'), "v2:" + testFieldKey('b')},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "v2", keyring.ActiveVersion())
+
+	tests := []struct {
+		name string
+		cfg  config.EncryptionConfig
+	}{
+		{"active key missing", config.EncryptionConfig{ActiveKey: "v3", Keys: []string{"v1:" + testFieldKey('a')}}},
+		{"malformed entry", config.EncryptionConfig{ActiveKey: "v1", Keys: []string{"v1"}}},
+		{"short key", config.EncryptionConfig{ActiveKey: "v1", Keys: []string{"v1:" + base64.StdEncoding.EncodeToString([]byte("short"))}}},
+		{"duplicate version", config.EncryptionConfig{ActiveKey: "v1", Keys: []string{"v1:" + testFieldKey('a'), "v1:" + testFieldKey('b')}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewFieldKeyringFromConfig(tt.cfg)
+			assert.Error(t, err)
+		})
+	}
+}
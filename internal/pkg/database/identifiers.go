@@ -0,0 +1,97 @@
+package database
+
+import (
+	"fmt"
+	"log"
+
+	"gorm.io/gorm"
+
+	"cloudpan/internal/repository/models"
+)
+
+// IdentifierDuplicate 重复或与墓碑冲突的标识符
+type IdentifierDuplicate struct {
+	Table  string `json:"table"`  // 表名
+	Column string `json:"column"` // 列名
+	Value  string `json:"value"`  // 标识符值
+	Count  int64  `json:"count"`  // 出现次数(包括软删除记录)
+	Reason string `json:"reason"` // 冲突原因(duplicate/tombstone)
+}
+
+// identifierColumn 需要全局唯一的标识符列
+type identifierColumn struct {
+	kind   string
+	table  string
+	column string
+}
+
+// identifierColumns 受保护的标识符列
+var identifierColumns = []identifierColumn{
+	{kind: models.TombstoneKindFileUUID, table: "files", column: "uuid"},
+	{kind: models.TombstoneKindShareCode, table: "file_shares", column: "share_code"},
+}
+
+// DetectIdentifierDuplicates 检测标识符冲突
+//
+// 检查范围包括软删除的记录：同一标识符出现多次，或仍在使用的标识符已存在墓碑，
+// 都会导致恢复/重新生成的实体与旧链接冲突
+func DetectIdentifierDuplicates(db *gorm.DB) ([]IdentifierDuplicate, error) {
+	var duplicates []IdentifierDuplicate
+	hasTombstones := db.Migrator().HasTable(&models.IdentifierTombstone{})
+
+	for _, ic := range identifierColumns {
+		if !db.Migrator().HasTable(ic.table) {
+			continue
+		}
+
+		var rows []struct {
+			Value string
+			Count int64
+		}
+		err := db.Table(ic.table).
+			Select(fmt.Sprintf("%s AS value, COUNT(*) AS count", ic.column)).
+			Group(ic.column).
+			Having("COUNT(*) > 1").
+			Scan(&rows).Error
+		if err != nil {
+			return nil, fmt.Errorf("检测 %s.%s 重复失败: %w", ic.table, ic.column, err)
+		}
+		for _, row := range rows {
+			duplicates = append(duplicates, IdentifierDuplicate{
+				Table: ic.table, Column: ic.column, Value: row.Value, Count: row.Count, Reason: "duplicate",
+			})
+		}
+
+		if !hasTombstones {
+			continue
+		}
+
+		var retired []string
+		err = db.Table(ic.table).
+			Joins(fmt.Sprintf("JOIN identifier_tombstones it ON it.kind = ? AND it.value = %s.%s", ic.table, ic.column), ic.kind).
+			Pluck(fmt.Sprintf("%s.%s", ic.table, ic.column), &retired).Error
+		if err != nil {
+			return nil, fmt.Errorf("检测 %s.%s 墓碑冲突失败: %w", ic.table, ic.column, err)
+		}
+		for _, value := range retired {
+			duplicates = append(duplicates, IdentifierDuplicate{
+				Table: ic.table, Column: ic.column, Value: value, Count: 1, Reason: "tombstone",
+			})
+		}
+	}
+
+	return duplicates, nil
+}
+
+// reportIdentifierDuplicates 迁移后报告标识符冲突，不阻断迁移
+func reportIdentifierDuplicates(db *gorm.DB) {
+	duplicates, err := DetectIdentifierDuplicates(db)
+	if err != nil {
+		log.Printf("Warning: failed to detect identifier duplicates: %v", err)
+		return
+	}
+	for _, d := range duplicates {
+		log.Printf("Warning: identifier conflict in %s.%s: value=%s count=%d reason=%s",
+			d.Table, d.Column, d.Value, d.Count, d.Reason)
+	}
+}
@@ -0,0 +1,30 @@
+package database
+
+import (
+	"fmt"
+	"log"
+
+	"cloudpan/internal/pkg/config"
+	basemodels "cloudpan/internal/pkg/database/models"
+)
+
+// InitIDGenerator 根据配置设置对外标识符生成器
+//
+// 切换生成器只影响新记录，已有的UUID和分享码保持不变，不需要数据迁移
+func InitIDGenerator() error {
+	if config.AppConfig == nil {
+		return fmt.Errorf("配置未初始化")
+	}
+
+	idConfig := config.AppConfig.Database.IDGenerator
+	generator, err := basemodels.NewIDGenerator(idConfig.Type, int64(idConfig.WorkerID))
+	if err != nil {
+		return fmt.Errorf("初始化ID生成器失败: %w", err)
+	}
+	basemodels.SetIDGenerator(generator)
+
+	if idConfig.Type != "" {
+		log.Printf("ID generator initialized: type=%s, worker_id=%d", idConfig.Type, idConfig.WorkerID)
+	}
+	return nil
+}
@@ -14,6 +14,16 @@ func Init() error {
 		return fmt.Errorf("failed to initialize MySQL connection pool: %w", err)
 	}
 
+	// 初始化敏感字段加密密钥环
+	if err := InitFieldEncryption(); err != nil {
+		return fmt.Errorf("failed to initialize field encryption: %w", err)
+	}
+
+	// 初始化对外标识符生成器
+	if err := InitIDGenerator(); err != nil {
+		return fmt.Errorf("failed to initialize ID generator: %w", err)
+	}
+
 	// 初始化并发控制机制（包括 Redis 分布式锁）
 	if err := InitConcurrencyControl(); err != nil {
 		return fmt.Errorf("failed to initialize concurrency control: %w", err)
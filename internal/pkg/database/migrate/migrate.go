@@ -0,0 +1,286 @@
+// Package migrate 基于版本号的SQL迁移引擎
+//
+// 迁移文件按版本号顺序执行，当前版本记录在 schema_migrations 表中。
+// MySQL的DDL不能回滚，每个迁移执行前将版本标记为dirty，成功后清除；
+// 迁移中途失败时数据库保持dirty，需要人工检查修复后用 Force 设置正确的版本才能继续
+//
+// 使用示例：
+//
+//	migrations, err := migrate.Load(os.DirFS("migrations"))
+//	migrator := migrate.NewMigrator(db, migrations, logger)
+//	err = migrator.Up(ctx)            // 执行所有未执行的迁移
+//	err = migrator.Rollback(ctx, 1)   // 回滚最近一个迁移
+//	err = migrator.Goto(ctx, 20)      // 升级或回滚到版本20
+//	err = migrator.Force(ctx, 20)     // 修复后直接设置版本，不执行SQL
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 迁移错误
+var (
+	ErrDirty            = errors.New("database is dirty")               // 上次迁移中途失败，需要修复后 force
+	ErrUnknownVersion   = errors.New("unknown migration version")       // 版本不在迁移列表中
+	ErrIrreversible     = errors.New("migration cannot be rolled back") // 回滚经过没有回滚文件或声明不可回滚的迁移
+	ErrInvalidArguments = errors.New("invalid migration arguments")
+)
+
+// schemaMigration 迁移状态表，只有一行，没有记录表示尚未执行任何迁移
+type schemaMigration struct {
+	Version   uint64    `gorm:"primaryKey;autoIncrement:false"`
+	Dirty     bool      `gorm:"not null"`
+	UpdatedAt time.Time `gorm:"not null"`
+}
+
+// TableName 迁移状态表名
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Status 一个迁移的执行状态
+type Status struct {
+	Version      uint64 `json:"version"`
+	Name         string `json:"name"`
+	Applied      bool   `json:"applied"`                // 版本不大于当前版本
+	HasDown      bool   `json:"has_down"`               // 是否可以回滚
+	Irreversible string `json:"irreversible,omitempty"` // 声明的不可回滚原因
+}
+
+// Migrator 迁移执行器
+type Migrator struct {
+	db         *gorm.DB
+	migrations []*Migration
+	logger     *zap.Logger
+	now        func() time.Time
+}
+
+// NewMigrator 创建迁移执行器，migrations 须按版本从小到大排列(Load 的返回值)，logger为nil时不记录日志
+func NewMigrator(db *gorm.DB, migrations []*Migration, logger *zap.Logger) *Migrator {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+		logger:     logger,
+		now:        time.Now,
+	}
+}
+
+// Version 返回当前版本和是否dirty，尚未执行任何迁移时版本为0
+func (m *Migrator) Version(ctx context.Context) (uint64, bool, error) {
+	if err := m.ensureTable(ctx); err != nil {
+		return 0, false, err
+	}
+
+	var rows []schemaMigration
+	if err := m.db.WithContext(ctx).Find(&rows).Error; err != nil {
+		return 0, false, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	if len(rows) == 0 {
+		return 0, false, nil
+	}
+	return rows[0].Version, rows[0].Dirty, nil
+}
+
+// Status 返回所有迁移及其执行状态，以及当前版本和是否dirty
+func (m *Migrator) Status(ctx context.Context) ([]Status, uint64, bool, error) {
+	current, dirty, err := m.Version(ctx)
+	if err != nil {
+		return nil, 0, false, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		statuses = append(statuses, Status{
+			Version:      migration.Version,
+			Name:         migration.Name,
+			Applied:      migration.Version <= current,
+			HasDown:      migration.HasDown,
+			Irreversible: migration.Irreversible,
+		})
+	}
+	return statuses, current, dirty, nil
+}
+
+// Up 按顺序执行所有未执行的迁移
+func (m *Migrator) Up(ctx context.Context) error {
+	if len(m.migrations) == 0 {
+		return nil
+	}
+	return m.Goto(ctx, m.migrations[len(m.migrations)-1].Version)
+}
+
+// Goto 升级或回滚到指定版本，target为0表示回滚全部迁移
+//
+// 回滚前检查经过的迁移都有回滚文件，有不可回滚的迁移时不做任何修改
+func (m *Migrator) Goto(ctx context.Context, target uint64) error {
+	if target != 0 && m.indexOf(target) < 0 {
+		return fmt.Errorf("%w: %d", ErrUnknownVersion, target)
+	}
+
+	current, dirty, err := m.Version(ctx)
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("%w at version %d: fix the schema manually, then force the correct version", ErrDirty, current)
+	}
+
+	switch {
+	case target > current:
+		return m.up(ctx, current, target)
+	case target < current:
+		return m.down(ctx, current, target)
+	default:
+		m.logger.Info("Schema is up to date", zap.Uint64("version", current))
+		return nil
+	}
+}
+
+// Rollback 回滚最近的steps个迁移
+func (m *Migrator) Rollback(ctx context.Context, steps int) error {
+	if steps <= 0 {
+		return fmt.Errorf("%w: steps must be positive", ErrInvalidArguments)
+	}
+
+	current, _, err := m.Version(ctx)
+	if err != nil {
+		return err
+	}
+	if current == 0 {
+		return nil
+	}
+
+	index := m.indexOf(current)
+	if index < 0 {
+		return fmt.Errorf("%w: current version %d", ErrUnknownVersion, current)
+	}
+
+	var target uint64
+	if index-steps >= 0 {
+		target = m.migrations[index-steps].Version
+	}
+	return m.Goto(ctx, target)
+}
+
+// Force 将版本设置为version并清除dirty标记，不执行任何SQL
+//
+// 用于迁移失败并人工修复后，或为已按SQL文件手动建好的数据库设置基线版本
+func (m *Migrator) Force(ctx context.Context, version uint64) error {
+	if version != 0 && m.indexOf(version) < 0 {
+		return fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+	if err := m.ensureTable(ctx); err != nil {
+		return err
+	}
+	m.logger.Warn("Forcing schema version", zap.Uint64("version", version))
+	return m.setVersion(ctx, version, false)
+}
+
+// up 依次执行版本在(current, target]之间的升级
+func (m *Migrator) up(ctx context.Context, current, target uint64) error {
+	for _, migration := range m.migrations {
+		if migration.Version <= current || migration.Version > target {
+			continue
+		}
+		if err := m.run(ctx, migration, migration.Up, migration.Version); err != nil {
+			return err
+		}
+		m.logger.Info("Applied migration", zap.Uint64("version", migration.Version), zap.String("name", migration.Name))
+	}
+	return nil
+}
+
+// down 从新到旧依次执行版本在(target, current]之间的回滚
+func (m *Migrator) down(ctx context.Context, current, target uint64) error {
+	index := m.indexOf(current)
+	if index < 0 {
+		return fmt.Errorf("%w: current version %d", ErrUnknownVersion, current)
+	}
+
+	for i := index; i >= 0 && m.migrations[i].Version > target; i-- {
+		if migration := m.migrations[i]; !migration.HasDown {
+			if migration.Irreversible != "" {
+				return fmt.Errorf("%w: %d_%s: %s", ErrIrreversible, migration.Version, migration.Name, migration.Irreversible)
+			}
+			return fmt.Errorf("%w: %d_%s has no down file", ErrIrreversible, migration.Version, migration.Name)
+		}
+	}
+
+	for i := index; i >= 0 && m.migrations[i].Version > target; i-- {
+		var previous uint64
+		if i > 0 {
+			previous = m.migrations[i-1].Version
+		}
+		if err := m.run(ctx, m.migrations[i], m.migrations[i].Down, previous); err != nil {
+			return err
+		}
+		m.logger.Info("Rolled back migration", zap.Uint64("version", m.migrations[i].Version), zap.String("name", m.migrations[i].Name))
+	}
+	return nil
+}
+
+// run 在同一连接上执行一个迁移的SQL，执行前标记dirty，成功后将版本设置为next
+func (m *Migrator) run(ctx context.Context, migration *Migration, script string, next uint64) error {
+	if err := m.setVersion(ctx, migration.Version, true); err != nil {
+		return err
+	}
+
+	// 同一连接执行，保证会话变量等在语句之间保持
+	err := m.db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		for _, statement := range SplitStatements(script) {
+			if err := conn.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("migration %d_%s failed, schema is left dirty: %w", migration.Version, migration.Name, err)
+	}
+
+	return m.setVersion(ctx, next, false)
+}
+
+// ensureTable 创建迁移状态表
+func (m *Migrator) ensureTable(ctx context.Context) error {
+	if err := m.db.WithContext(ctx).AutoMigrate(&schemaMigration{}); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	return nil
+}
+
+// setVersion 写入当前版本，版本为0且不dirty时清空状态表
+func (m *Migrator) setVersion(ctx context.Context, version uint64, dirty bool) error {
+	err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&schemaMigration{}).Error; err != nil {
+			return err
+		}
+		if version == 0 && !dirty {
+			return nil
+		}
+		return tx.Create(&schemaMigration{Version: version, Dirty: dirty, UpdatedAt: m.now()}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record schema version %d: %w", version, err)
+	}
+	return nil
+}
+
+// indexOf 返回版本在迁移列表中的位置，不存在时返回-1
+func (m *Migrator) indexOf(version uint64) int {
+	for i, migration := range m.migrations {
+		if migration.Version == version {
+			return i
+		}
+	}
+	return -1
+}
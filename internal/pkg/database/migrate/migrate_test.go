@@ -0,0 +1,216 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	_ "modernc.org/sqlite" // 使用纯Go的SQLite驱动
+
+	"cloudpan/migrations"
+)
+
+func setupMigrateTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	sqlDB, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "migrate.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+	db, err := gorm.Open(sqlite.Dialector{Conn: sqlDB}, &gorm.Config{})
+	require.NoError(t, err)
+	return db
+}
+
+// testMigrations 三个迁移，版本2没有回滚文件
+var testMigrations = fstest.MapFS{
+	"001_create_a.up.sql":   {Data: []byte("CREATE TABLE a (id integer);\nINSERT INTO a VALUES (1);")},
+	"001_create_a.down.sql": {Data: []byte("DROP TABLE a;")},
+	"002_create_b.sql":      {Data: []byte("-- 早期迁移，没有回滚文件\nCREATE TABLE b (id integer);")},
+	"003_create_c.up.sql":   {Data: []byte("CREATE TABLE c (id integer);")},
+	"003_create_c.down.sql": {Data: []byte("DROP TABLE c;")},
+	"README.md":             {Data: []byte("ignored")},
+}
+
+func newTestMigrator(t *testing.T) (*Migrator, *gorm.DB) {
+	migrations, err := Load(testMigrations)
+	require.NoError(t, err)
+	db := setupMigrateTestDB(t)
+	return NewMigrator(db, migrations, nil), db
+}
+
+func assertVersion(t *testing.T, m *Migrator, expected uint64, expectedDirty bool) {
+	t.Helper()
+	version, dirty, err := m.Version(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, expected, version)
+	assert.Equal(t, expectedDirty, dirty)
+}
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load(testMigrations)
+	require.NoError(t, err)
+	require.Len(t, migrations, 3)
+	assert.Equal(t, uint64(1), migrations[0].Version)
+	assert.Equal(t, "create_a", migrations[0].Name)
+	assert.True(t, migrations[0].HasDown)
+	assert.False(t, migrations[1].HasDown)
+
+	_, err = Load(fstest.MapFS{"001_a.down.sql": {Data: []byte("")}})
+	assert.Error(t, err, "只有回滚文件")
+	_, err = Load(fstest.MapFS{"001_a.sql": {}, "001_a.up.sql": {}})
+	assert.Error(t, err, "重复的升级文件")
+	_, err = Load(fstest.MapFS{"001_a.up.sql": {}, "001_b.down.sql": {}})
+	assert.Error(t, err, "同一版本名称不一致")
+}
+
+func TestLoad_EmbeddedMigrations(t *testing.T) {
+	loaded, err := Load(migrations.FS)
+	require.NoError(t, err)
+	require.NotEmpty(t, loaded)
+	for i := 1; i < len(loaded); i++ {
+		assert.Greater(t, loaded[i].Version, loaded[i-1].Version)
+	}
+}
+
+// TestLoad_EmbeddedMigrationsDeclareRollback 基础架构(001-007)之后的迁移都有回滚文件或明确声明不可回滚
+func TestLoad_EmbeddedMigrationsDeclareRollback(t *testing.T) {
+	loaded, err := Load(migrations.FS)
+	require.NoError(t, err)
+	for _, migration := range loaded {
+		if migration.Version <= 7 {
+			continue
+		}
+		assert.True(t, migration.HasDown || migration.Irreversible != "",
+			"%03d_%s needs a down file", migration.Version, migration.Name)
+	}
+}
+
+func TestMigrator_DeclaredIrreversible(t *testing.T) {
+	ctx := context.Background()
+	loaded, err := Load(fstest.MapFS{
+		"001_create_a.up.sql":    {Data: []byte("CREATE TABLE a (id integer);")},
+		"001_create_a.down.sql":  {Data: []byte("DROP TABLE a;")},
+		"002_encrypt_a.sql":      {Data: []byte("ALTER TABLE a ADD COLUMN secret text;")},
+		"002_encrypt_a.down.sql": {Data: []byte("-- 密文无法还原\n-- irreversible: a.secret holds ciphertext\n")},
+	})
+	require.NoError(t, err)
+	require.Len(t, loaded, 2)
+	assert.False(t, loaded[1].HasDown)
+	assert.Equal(t, "a.secret holds ciphertext", loaded[1].Irreversible)
+
+	db := setupMigrateTestDB(t)
+	m := NewMigrator(db, loaded, nil)
+	require.NoError(t, m.Up(ctx))
+
+	err = m.Rollback(ctx, 1)
+	assert.ErrorIs(t, err, ErrIrreversible)
+	assert.Contains(t, err.Error(), "a.secret holds ciphertext")
+	assertVersion(t, m, 2, false)
+
+	statuses, _, _, err := m.Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "a.secret holds ciphertext", statuses[1].Irreversible)
+}
+
+func TestSplitStatements(t *testing.T) {
+	script := `-- 注释; 不拆分
+CREATE TABLE t (name varchar(10) DEFAULT 'a;b'); # 行尾注释
+/*!40101 SET NAMES utf8mb4 */;
+INSERT INTO t VALUES ('it''s; ok');
+
+DELIMITER $$
+CREATE PROCEDURE p()
+BEGIN
+  SELECT 1;
+END$$
+DELIMITER ;
+CALL p();`
+
+	assert.Equal(t, []string{
+		"CREATE TABLE t (name varchar(10) DEFAULT 'a;b')",
+		"/*!40101 SET NAMES utf8mb4 */",
+		"INSERT INTO t VALUES ('it''s; ok')",
+		"CREATE PROCEDURE p()\nBEGIN\n  SELECT 1;\nEND",
+		"CALL p()",
+	}, SplitStatements(script))
+	assert.Empty(t, SplitStatements("-- only comments\n\n"))
+}
+
+func TestMigrator_UpAndRollback(t *testing.T) {
+	ctx := context.Background()
+	m, db := newTestMigrator(t)
+
+	assertVersion(t, m, 0, false)
+	require.NoError(t, m.Up(ctx))
+	assertVersion(t, m, 3, false)
+	assert.True(t, db.Migrator().HasTable("c"))
+	require.NoError(t, m.Up(ctx), "没有未执行的迁移时不做任何修改")
+
+	statuses, current, dirty, err := m.Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), current)
+	assert.False(t, dirty)
+	assert.Len(t, statuses, 3)
+	assert.True(t, statuses[2].Applied)
+
+	require.NoError(t, m.Rollback(ctx, 1))
+	assertVersion(t, m, 2, false)
+	assert.False(t, db.Migrator().HasTable("c"))
+
+	err = m.Rollback(ctx, 1)
+	assert.ErrorIs(t, err, ErrIrreversible)
+	assertVersion(t, m, 2, false)
+	assert.True(t, db.Migrator().HasTable("b"), "不可回滚时不做任何修改")
+
+	assert.ErrorIs(t, m.Rollback(ctx, 0), ErrInvalidArguments)
+}
+
+func TestMigrator_Goto(t *testing.T) {
+	ctx := context.Background()
+	m, db := newTestMigrator(t)
+
+	require.NoError(t, m.Goto(ctx, 1))
+	assertVersion(t, m, 1, false)
+	assert.False(t, db.Migrator().HasTable("b"))
+
+	require.NoError(t, m.Goto(ctx, 3))
+	assertVersion(t, m, 3, false)
+
+	assert.ErrorIs(t, m.Goto(ctx, 7), ErrUnknownVersion)
+
+	// 版本2不可回滚：人工删除表b后强制设置为版本1，再回滚全部
+	require.NoError(t, m.Goto(ctx, 2))
+	require.NoError(t, db.Exec("DROP TABLE b").Error)
+	require.NoError(t, m.Force(ctx, 1))
+	require.NoError(t, m.Goto(ctx, 0))
+	assertVersion(t, m, 0, false)
+	assert.False(t, db.Migrator().HasTable("a"))
+}
+
+func TestMigrator_DirtyAfterFailure(t *testing.T) {
+	ctx := context.Background()
+	loaded, err := Load(fstest.MapFS{
+		"001_ok.up.sql":     {Data: []byte("CREATE TABLE ok (id integer);")},
+		"002_broken.up.sql": {Data: []byte("CREATE TABLE half (id integer);\nCREATE TABLE ok (id integer);")},
+	})
+	require.NoError(t, err)
+	m := NewMigrator(setupMigrateTestDB(t), loaded, nil)
+
+	err = m.Up(ctx)
+	require.Error(t, err)
+	assertVersion(t, m, 2, true)
+
+	assert.ErrorIs(t, m.Up(ctx), ErrDirty, "dirty时拒绝继续迁移")
+	assert.ErrorIs(t, m.Rollback(ctx, 1), ErrDirty)
+
+	// 人工修复后force
+	require.NoError(t, m.Force(ctx, 2))
+	assertVersion(t, m, 2, false)
+	require.NoError(t, m.Up(ctx))
+	assert.ErrorIs(t, m.Force(ctx, 9), ErrUnknownVersion)
+}
@@ -0,0 +1,94 @@
+package migrate
+
+import (
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Migration 一个版本的迁移
+type Migration struct {
+	Version      uint64 // 版本号，取自文件名前缀
+	Name         string // 名称，取自文件名
+	Up           string // 升级SQL
+	Down         string // 回滚SQL
+	HasDown      bool   // 是否有回滚文件，没有时不能回滚到该版本之前
+	Irreversible string // 回滚文件声明的不可回滚原因，不为空时 HasDown 为false
+}
+
+// fileNamePattern 迁移文件名：版本_名称.up.sql、版本_名称.down.sql，
+// 没有 .up/.down 后缀的 版本_名称.sql 视为升级文件，可以另有对应的 .down.sql
+var fileNamePattern = regexp.MustCompile(`^(\d+)_([^.]+)(\.up|\.down)?\.sql$`)
+
+// irreversiblePattern 回滚文件中声明迁移不可回滚的注释行：-- irreversible: 原因
+var irreversiblePattern = regexp.MustCompile(`(?m)^--\s*irreversible:\s*(.+)$`)
+
+// Load 读取fsys根目录下的迁移文件，按版本从小到大返回，不符合命名的文件被忽略
+//
+// 同一版本的文件名称必须一致，且只能有一个升级文件和一个回滚文件；
+// 回滚文件包含 "-- irreversible: 原因" 注释行时表示该迁移明确不可回滚，不执行其中的SQL
+func Load(fsys fs.FS) ([]*Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[uint64]*Migration)
+	hasUp := make(map[uint64]bool)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		match := fileNamePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil || version == 0 {
+			return nil, fmt.Errorf("invalid migration version in %s", entry.Name())
+		}
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, migration.Name, match[2])
+		}
+
+		if match[3] == ".down" {
+			if migration.HasDown || migration.Irreversible != "" {
+				return nil, fmt.Errorf("migration %d has more than one down file", version)
+			}
+			if declared := irreversiblePattern.FindStringSubmatch(string(content)); declared != nil {
+				migration.Irreversible = strings.TrimSpace(declared[1])
+				continue
+			}
+			migration.Down, migration.HasDown = string(content), true
+			continue
+		}
+		if hasUp[version] {
+			return nil, fmt.Errorf("migration %d has more than one up file", version)
+		}
+		migration.Up, hasUp[version] = string(content), true
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for version, migration := range byVersion {
+		if !hasUp[version] {
+			return nil, fmt.Errorf("migration %d has a down file but no up file", version)
+		}
+		migrations = append(migrations, migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
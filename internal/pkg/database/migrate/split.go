@@ -0,0 +1,76 @@
+package migrate
+
+import "strings"
+
+// SplitStatements 将SQL脚本拆分为逐条执行的语句
+//
+// 支持MySQL客户端的 DELIMITER 指令(存储过程、触发器)，忽略引号和注释中的分隔符，
+// 去掉 -- 和 # 行注释，保留 /* */ 块注释(MySQL的 /*! */ 可执行注释需要原样发送)
+func SplitStatements(script string) []string {
+	var (
+		statements   []string
+		buf          strings.Builder
+		delimiter    = ";"
+		quote        byte
+		blockComment bool
+	)
+
+	flush := func() {
+		if statement := strings.TrimSpace(buf.String()); statement != "" {
+			statements = append(statements, statement)
+		}
+		buf.Reset()
+	}
+
+	for _, line := range strings.SplitAfter(script, "\n") {
+		if quote == 0 && !blockComment && strings.TrimSpace(buf.String()) == "" {
+			fields := strings.Fields(line)
+			if len(fields) == 2 && strings.EqualFold(fields[0], "DELIMITER") {
+				delimiter = fields[1]
+				continue
+			}
+		}
+
+		for i := 0; i < len(line); i++ {
+			c := line[i]
+			switch {
+			case blockComment:
+				buf.WriteByte(c)
+				if c == '*' && i+1 < len(line) && line[i+1] == '/' {
+					buf.WriteByte('/')
+					i++
+					blockComment = false
+				}
+			case quote != 0:
+				buf.WriteByte(c)
+				if c == '\\' && quote != '`' && i+1 < len(line) {
+					buf.WriteByte(line[i+1])
+					i++
+				} else if c == quote {
+					quote = 0
+				}
+			case c == '#' || (c == '-' && strings.HasPrefix(line[i:], "--") && (i+2 == len(line) || line[i+2] == ' ' || line[i+2] == '\t' || line[i+2] == '\n' || line[i+2] == '\r')):
+				// 行注释，保留换行
+				if strings.HasSuffix(line, "\n") {
+					buf.WriteByte('\n')
+				}
+				i = len(line)
+			case c == '/' && i+1 < len(line) && line[i+1] == '*':
+				buf.WriteString("/*")
+				i++
+				blockComment = true
+			case c == '\'' || c == '"' || c == '`':
+				buf.WriteByte(c)
+				quote = c
+			case strings.HasPrefix(line[i:], delimiter):
+				flush()
+				i += len(delimiter) - 1
+			default:
+				buf.WriteByte(c)
+			}
+		}
+	}
+	flush()
+
+	return statements
+}
@@ -8,6 +8,7 @@ import (
 	"gorm.io/gorm"
 
 	"cloudpan/internal/pkg/database/models"
+	"cloudpan/internal/pkg/indexing"
 )
 
 // MigrationConfig 迁移配置
@@ -68,6 +69,9 @@ func collectModels() []interface{} {
 
 // performMigration 执行迁移操作
 func performMigration(db *gorm.DB, models []interface{}, config *MigrationConfig) error {
+	// 迁移期间暂停索引发布，数据修复产生的变更由迁移后的全量重建处理
+	defer indexing.Suspend()()
+
 	// 如果需要先删除表
 	if config.DropFirst {
 		log.Println("Dropping existing tables...")
@@ -81,6 +85,9 @@ func performMigration(db *gorm.DB, models []interface{}, config *MigrationConfig
 		return fmt.Errorf("failed to auto migrate: %w", err)
 	}
 
+	// 检测标识符冲突(包括软删除记录)
+	reportIdentifierDuplicates(db)
+
 	// 创建索引
 	if config.CreateIndex {
 		if err := createIndexes(db, models); err != nil {
@@ -11,6 +11,8 @@ func RegisterAllModels() {
 	RegisterModel("UserSession", &models.UserSession{})
 	RegisterModel("UserLoginHistory", &models.UserLoginHistory{})
 	RegisterModel("UserPreference", &models.UserPreference{})
+	RegisterModel("UserTwoFactor", &models.UserTwoFactor{})
+	RegisterModel("UserInactivity", &models.UserInactivity{})
 
 	// 文件相关模型
 	RegisterModel("File", &models.File{})
@@ -18,6 +20,13 @@ func RegisterAllModels() {
 	RegisterModel("FileShare", &models.FileShare{})
 	RegisterModel("FileTag", &models.FileTag{})
 	RegisterModel("FileUploadChunk", &models.FileUploadChunk{})
+	RegisterModel("FolderRule", &models.FolderRule{})
+	RegisterModel("FolderRuleLog", &models.FolderRuleLog{})
+	RegisterModel("FileGrant", &models.FileGrant{})
+	RegisterModel("RetainedObject", &models.RetainedObject{})
+	RegisterModel("ShareAbuseReport", &models.ShareAbuseReport{})
+	RegisterModel("ContentModerationResult", &models.ContentModerationResult{})
+	RegisterModel("FileTransfer", &models.FileTransfer{})
 
 	// 团队相关模型
 	RegisterModel("Team", &models.Team{})
@@ -40,8 +49,10 @@ func RegisterAllModels() {
 	// 系统相关模型
 	RegisterModel("RecycleBin", &models.RecycleBin{})
 	RegisterModel("AuditLog", &models.AuditLog{})
+	RegisterModel("AdminAuditLog", &models.AdminAuditLog{})
 	RegisterModel("SystemSetting", &models.SystemSetting{})
 	RegisterModel("PasswordResetToken", &models.PasswordResetToken{})
+	RegisterModel("IdentifierTombstone", &models.IdentifierTombstone{})
 
 	// 新增模型
 	RegisterModel("FileComment", &models.FileComment{})
@@ -78,6 +89,8 @@ func RegisterAllModels() {
 	RegisterModel("APIToken", &models.APIToken{})
 	RegisterModel("Webhook", &models.Webhook{})
 	RegisterModel("APILog", &models.APILog{})
+	RegisterModel("APIUsageDaily", &models.APIUsageDaily{})
+	RegisterModel("APIKey", &models.APIKey{})
 
 	// 多语言支持模型
 	RegisterModel("Language", &models.Language{})
@@ -97,6 +110,8 @@ func GetAllModels() []interface{} {
 		&models.UserSession{},
 		&models.UserLoginHistory{},
 		&models.UserPreference{},
+		&models.UserTwoFactor{},
+		&models.UserInactivity{},
 
 		// 文件相关模型
 		&models.File{},
@@ -104,6 +119,13 @@ func GetAllModels() []interface{} {
 		&models.FileShare{},
 		&models.FileTag{},
 		&models.FileUploadChunk{},
+		&models.FolderRule{},
+		&models.FolderRuleLog{},
+		&models.FileGrant{},
+		&models.RetainedObject{},
+		&models.ShareAbuseReport{},
+		&models.ContentModerationResult{},
+		&models.FileTransfer{},
 
 		// 团队相关模型
 		&models.Team{},
@@ -126,8 +148,10 @@ func GetAllModels() []interface{} {
 		// 系统相关模型
 		&models.RecycleBin{},
 		&models.AuditLog{},
+		&models.AdminAuditLog{},
 		&models.SystemSetting{},
 		&models.PasswordResetToken{},
+		&models.IdentifierTombstone{},
 
 		// 新增模型
 		&models.FileComment{},
@@ -164,6 +188,8 @@ func GetAllModels() []interface{} {
 		&models.APIToken{},
 		&models.Webhook{},
 		&models.APILog{},
+		&models.APIUsageDaily{},
+		&models.APIKey{},
 
 		// 多语言支持模型
 		&models.Language{},
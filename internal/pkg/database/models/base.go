@@ -12,7 +12,6 @@ import (
 	"strings"
 	"time"
 
-	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
@@ -308,12 +307,15 @@ func (j *JSONMap) Scan(value interface{}) error {
 
 // 辅助函数
 
-// GenerateUUID 生成UUID
+// GenerateUUID 生成对外标识符，格式由全局ID生成器决定(默认UUIDv4)
 func GenerateUUID() string {
-	return uuid.New().String()
+	return currentIDGenerator().NewID()
 }
 
 // GenerateShareCode 生成分享码
+//
+// 分享码本身是访问凭证，始终使用crypto/rand生成的随机字符串，不使用ID生成器：
+// ULID同一毫秒内随机部分递增、雪花ID可被推算，都不能保证不可猜测
 func GenerateShareCode() string {
 	return GenerateRandomString(8)
 }
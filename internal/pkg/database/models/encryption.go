@@ -0,0 +1,298 @@
+package models
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm/schema"
+
+	"cloudpan/internal/pkg/utils"
+)
+
+// EncryptedSerializerName 敏感字段加密序列化器名称，模型字段使用 `gorm:"serializer:encrypted"` 启用
+const EncryptedSerializerName = "encrypted"
+
+// encryptedPrefix 加密值前缀，完整格式为 enc:<密钥版本>:<加密主体>:<密文>
+const encryptedPrefix = "enc:"
+
+// 字段加密相关错误
+var (
+	ErrFieldKeyringNotConfigured = errors.New("field encryption keyring not configured")
+	ErrFieldKeyNotFound          = errors.New("field encryption key version not found")
+	ErrInvalidEncryptedValue     = errors.New("invalid encrypted field value")
+)
+
+// EncryptionSubject 加密主体接口
+//
+// 模型实现该接口后，加密密钥会按主体(如用户UUID)从主密钥派生，
+// 不同用户的数据使用不同的密钥加密
+type EncryptionSubject interface {
+	EncryptionSubject() string
+}
+
+// FieldKeyring 字段加密密钥环
+//
+// 支持多个密钥版本：新数据始终使用当前版本加密，旧版本密钥仅用于解密，
+// 密钥轮换后通过重加密工具把旧数据迁移到新版本
+type FieldKeyring struct {
+	activeVersion string
+	keys          map[string][]byte
+}
+
+// NewFieldKeyring 创建字段加密密钥环
+//
+// keys 为 版本 -> Base64编码的32字节主密钥；activeVersion 必须存在于keys中
+func NewFieldKeyring(activeVersion string, keys map[string]string) (*FieldKeyring, error) {
+	if activeVersion == "" {
+		return nil, fmt.Errorf("未指定当前加密密钥版本")
+	}
+
+	keyring := &FieldKeyring{
+		activeVersion: activeVersion,
+		keys:          make(map[string][]byte, len(keys)),
+	}
+	for version, encoded := range keys {
+		if version == "" || strings.Contains(version, ":") {
+			return nil, fmt.Errorf("无效的密钥版本: %q", version)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("密钥 %s 不是有效的Base64: %w", version, err)
+		}
+		if len(key) != 32 {
+			return nil, fmt.Errorf("密钥 %s 长度必须为32字节，实际为 %d 字节", version, len(key))
+		}
+		keyring.keys[version] = key
+	}
+
+	if _, ok := keyring.keys[activeVersion]; !ok {
+		return nil, fmt.Errorf("当前密钥版本 %s: %w", activeVersion, ErrFieldKeyNotFound)
+	}
+	return keyring, nil
+}
+
+// ParseFieldKeys 解析 版本:Base64密钥 格式的密钥列表
+func ParseFieldKeys(entries []string) (map[string]string, error) {
+	keys := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		version, key, ok := strings.Cut(entry, ":")
+		if !ok || version == "" || key == "" {
+			return nil, fmt.Errorf("密钥格式应为 版本:Base64密钥")
+		}
+		if _, exists := keys[version]; exists {
+			return nil, fmt.Errorf("密钥版本 %s 重复", version)
+		}
+		keys[version] = key
+	}
+	return keys, nil
+}
+
+// ActiveVersion 当前加密使用的密钥版本
+func (k *FieldKeyring) ActiveVersion() string {
+	return k.activeVersion
+}
+
+// Encrypt 使用当前版本密钥加密明文
+func (k *FieldKeyring) Encrypt(plaintext, subject string) (string, error) {
+	if strings.Contains(subject, ":") {
+		return "", fmt.Errorf("加密主体不能包含冒号: %w", ErrInvalidEncryptedValue)
+	}
+
+	ciphertext, err := utils.EncryptAES(plaintext, k.deriveKey(k.activeVersion, subject))
+	if err != nil {
+		return "", fmt.Errorf("加密字段失败: %w", err)
+	}
+	return encryptedPrefix + k.activeVersion + ":" + subject + ":" + ciphertext, nil
+}
+
+// Decrypt 解密加密值，未加密的历史明文原样返回
+func (k *FieldKeyring) Decrypt(value string) (string, error) {
+	if !IsEncryptedValue(value) {
+		return value, nil
+	}
+
+	version, subject, ciphertext, err := parseEncryptedValue(value)
+	if err != nil {
+		return "", err
+	}
+	if _, ok := k.keys[version]; !ok {
+		return "", fmt.Errorf("密钥版本 %s: %w", version, ErrFieldKeyNotFound)
+	}
+
+	plaintext, err := utils.DecryptAES(ciphertext, k.deriveKey(version, subject))
+	if err != nil {
+		return "", fmt.Errorf("解密字段失败: %w", err)
+	}
+	return plaintext, nil
+}
+
+// NeedsReencrypt 判断值是否需要重加密(未加密或不是当前密钥版本)
+func (k *FieldKeyring) NeedsReencrypt(value string) bool {
+	if !IsEncryptedValue(value) {
+		return true
+	}
+	version, _, _, err := parseEncryptedValue(value)
+	return err != nil || version != k.activeVersion
+}
+
+// deriveKey 按主体从主密钥派生数据密钥(Base64编码)
+func (k *FieldKeyring) deriveKey(version, subject string) string {
+	mac := hmac.New(sha256.New, k.keys[version])
+	mac.Write([]byte("cloudpan-field-encryption:" + subject))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// IsEncryptedValue 判断数据库中的值是否为加密格式
+func IsEncryptedValue(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix)
+}
+
+// EncryptedValueSubject 返回加密值中记录的加密主体
+func EncryptedValueSubject(value string) string {
+	_, subject, _, err := parseEncryptedValue(value)
+	if err != nil {
+		return ""
+	}
+	return subject
+}
+
+// parseEncryptedValue 解析加密值中的版本、主体和密文
+func parseEncryptedValue(value string) (version, subject, ciphertext string, err error) {
+	parts := strings.SplitN(strings.TrimPrefix(value, encryptedPrefix), ":", 3)
+	if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+		return "", "", "", ErrInvalidEncryptedValue
+	}
+	return parts[0], parts[1], parts[2], nil
+}
+
+var (
+	fieldKeyringMu sync.RWMutex
+	fieldKeyring   *FieldKeyring
+)
+
+// SetFieldKeyring 设置全局字段加密密钥环，传入nil表示关闭加密
+func SetFieldKeyring(keyring *FieldKeyring) {
+	fieldKeyringMu.Lock()
+	defer fieldKeyringMu.Unlock()
+	fieldKeyring = keyring
+}
+
+// GetFieldKeyring 获取全局字段加密密钥环，未配置时返回nil
+func GetFieldKeyring() *FieldKeyring {
+	fieldKeyringMu.RLock()
+	defer fieldKeyringMu.RUnlock()
+	return fieldKeyring
+}
+
+// EncryptedSerializer 敏感字段加密序列化器
+//
+// 写入时使用全局密钥环加密，读取时透明解密；未配置密钥环时按明文写入，
+// 读取时兼容加密前遗留的明文数据。支持 string 和 *string 字段
+type EncryptedSerializer struct{}
+
+// Scan 从数据库值解密并写入模型字段
+func (EncryptedSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	fieldValue := reflect.New(field.FieldType)
+
+	if dbValue != nil {
+		var raw string
+		switch v := dbValue.(type) {
+		case []byte:
+			raw = string(v)
+		case string:
+			raw = v
+		default:
+			return fmt.Errorf("字段 %s 不支持的加密值类型: %T", field.Name, dbValue)
+		}
+
+		plaintext := raw
+		if IsEncryptedValue(raw) {
+			keyring := GetFieldKeyring()
+			if keyring == nil {
+				return fmt.Errorf("字段 %s: %w", field.Name, ErrFieldKeyringNotConfigured)
+			}
+			var err error
+			if plaintext, err = keyring.Decrypt(raw); err != nil {
+				return fmt.Errorf("字段 %s: %w", field.Name, err)
+			}
+		}
+
+		if err := setStringValue(fieldValue.Elem(), plaintext); err != nil {
+			return fmt.Errorf("字段 %s: %w", field.Name, err)
+		}
+	}
+
+	field.ReflectValueOf(ctx, dst).Set(fieldValue.Elem())
+	return nil
+}
+
+// Value 加密模型字段值用于写入数据库
+func (EncryptedSerializer) Value(_ context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	var plaintext string
+	switch v := fieldValue.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		plaintext = v
+	case *string:
+		if v == nil {
+			return nil, nil
+		}
+		plaintext = *v
+	default:
+		return nil, fmt.Errorf("字段 %s 不支持的加密字段类型: %T", field.Name, fieldValue)
+	}
+
+	keyring := GetFieldKeyring()
+	if keyring == nil {
+		return plaintext, nil
+	}
+	return keyring.Encrypt(plaintext, encryptionSubjectOf(dst))
+}
+
+// setStringValue 将明文写入 string 或 *string 类型的值
+func setStringValue(v reflect.Value, plaintext string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(plaintext)
+	case reflect.Ptr:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("不支持的加密字段类型: %s", v.Type())
+		}
+		v.Set(reflect.ValueOf(&plaintext))
+	default:
+		return fmt.Errorf("不支持的加密字段类型: %s", v.Type())
+	}
+	return nil
+}
+
+// encryptionSubjectOf 获取模型的加密主体，未实现 EncryptionSubject 时返回空字符串(使用主密钥派生的公共密钥)
+func encryptionSubjectOf(dst reflect.Value) string {
+	if !dst.IsValid() {
+		return ""
+	}
+	if dst.Kind() != reflect.Ptr && dst.CanAddr() {
+		dst = dst.Addr()
+	}
+	if dst.CanInterface() {
+		if subject, ok := dst.Interface().(EncryptionSubject); ok {
+			return subject.EncryptionSubject()
+		}
+	}
+	return ""
+}
+
+func init() {
+	schema.RegisterSerializer(EncryptedSerializerName, EncryptedSerializer{})
+}
@@ -0,0 +1,220 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ID生成器类型
+const (
+	IDGeneratorUUID      = "uuid"      // 随机UUIDv4(默认)
+	IDGeneratorULID      = "ulid"      // 按时间有序的ULID
+	IDGeneratorSnowflake = "snowflake" // 雪花算法，需要为每个实例分配不同的worker ID
+)
+
+// 雪花算法位分配：41位毫秒时间戳 + 10位worker ID + 12位序列号
+const (
+	snowflakeWorkerBits   = 10
+	snowflakeSequenceBits = 12
+	MaxSnowflakeWorkerID  = 1<<snowflakeWorkerBits - 1
+	snowflakeSequenceMask = 1<<snowflakeSequenceBits - 1
+)
+
+// snowflakeEpoch 雪花ID的起始时间，41位时间戳可使用约69年
+var snowflakeEpoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// crockfordAlphabet ULID使用的Crockford Base32字符表(不含I、L、O、U)
+const crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// IDGenerator 对外标识符生成器
+//
+// 生成的标识符用于模型的UUID列，长度不超过36个字符。自增主键仍只在内部使用，
+// 对外暴露的标识符不应泄露数据量：
+//   - uuid: 完全随机，写入时索引页分裂较多
+//   - ulid: 毫秒时间戳前缀加80位随机数，按时间有序，索引局部性好；同一毫秒内随机部分递增，不能用于访问凭证
+//   - snowflake: 64位整数，按时间有序且最短，但可被推算，不能用于访问凭证
+type IDGenerator interface {
+	NewID() string
+}
+
+var (
+	idGeneratorMu sync.RWMutex
+	idGenerator   IDGenerator = UUIDGenerator{}
+)
+
+// SetIDGenerator 设置全局标识符生成器，传入nil时恢复为UUIDv4
+func SetIDGenerator(generator IDGenerator) {
+	idGeneratorMu.Lock()
+	defer idGeneratorMu.Unlock()
+	if generator == nil {
+		generator = UUIDGenerator{}
+	}
+	idGenerator = generator
+}
+
+// currentIDGenerator 获取全局标识符生成器
+func currentIDGenerator() IDGenerator {
+	idGeneratorMu.RLock()
+	defer idGeneratorMu.RUnlock()
+	return idGenerator
+}
+
+// NewIDGenerator 按类型创建标识符生成器，类型为空时使用UUIDv4
+//
+// workerID 仅对雪花算法有效，取值范围0-1023，多实例部署时必须各不相同
+func NewIDGenerator(kind string, workerID int64) (IDGenerator, error) {
+	switch strings.ToLower(strings.TrimSpace(kind)) {
+	case "", IDGeneratorUUID:
+		return UUIDGenerator{}, nil
+	case IDGeneratorULID:
+		return NewULIDGenerator(), nil
+	case IDGeneratorSnowflake:
+		return NewSnowflakeGenerator(workerID)
+	default:
+		return nil, fmt.Errorf("不支持的ID生成器类型: %s", kind)
+	}
+}
+
+// UUIDGenerator 随机UUIDv4生成器
+type UUIDGenerator struct{}
+
+// NewID 生成UUIDv4
+func (UUIDGenerator) NewID() string {
+	return uuid.New().String()
+}
+
+// ULIDGenerator ULID生成器
+//
+// 同一毫秒内生成的ID在随机部分上递增，保证单实例内严格有序
+type ULIDGenerator struct {
+	mu      sync.Mutex
+	now     func() time.Time
+	lastMs  uint64
+	lastRnd [10]byte
+}
+
+// NewULIDGenerator 创建ULID生成器
+func NewULIDGenerator() *ULIDGenerator {
+	return &ULIDGenerator{now: time.Now}
+}
+
+// NewID 生成26位ULID
+func (g *ULIDGenerator) NewID() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ms := uint64(g.now().UnixMilli())
+	if ms <= g.lastMs {
+		// 同一毫秒或时钟回拨：沿用上次的时间戳并递增随机部分
+		ms = g.lastMs
+		if !incrementBytes(g.lastRnd[:]) {
+			// 随机部分溢出，借用下一毫秒
+			ms++
+			fillRandom(g.lastRnd[:])
+		}
+	} else {
+		fillRandom(g.lastRnd[:])
+	}
+	g.lastMs = ms
+
+	var raw [16]byte
+	raw[0] = byte(ms >> 40)
+	raw[1] = byte(ms >> 32)
+	binary.BigEndian.PutUint32(raw[2:6], uint32(ms))
+	copy(raw[6:], g.lastRnd[:])
+	return encodeCrockford(raw)
+}
+
+// SnowflakeGenerator 雪花ID生成器
+type SnowflakeGenerator struct {
+	mu       sync.Mutex
+	now      func() time.Time
+	sleep    func(time.Duration)
+	workerID int64
+	lastMs   int64
+	sequence int64
+}
+
+// NewSnowflakeGenerator 创建雪花ID生成器
+func NewSnowflakeGenerator(workerID int64) (*SnowflakeGenerator, error) {
+	if workerID < 0 || workerID > MaxSnowflakeWorkerID {
+		return nil, fmt.Errorf("雪花算法worker ID必须在0到 %d 之间", MaxSnowflakeWorkerID)
+	}
+	return &SnowflakeGenerator{
+		now:      time.Now,
+		sleep:    time.Sleep,
+		workerID: workerID,
+	}, nil
+}
+
+// NewID 生成十进制雪花ID
+func (g *SnowflakeGenerator) NewID() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ms := g.now().Sub(snowflakeEpoch).Milliseconds()
+	if ms < g.lastMs {
+		// 时钟回拨时沿用上次的时间戳，避免生成重复ID
+		ms = g.lastMs
+	}
+	if ms == g.lastMs {
+		g.sequence = (g.sequence + 1) & snowflakeSequenceMask
+		if g.sequence == 0 {
+			// 当前毫秒的序列号用完，等待进入下一毫秒
+			for ms <= g.lastMs {
+				g.sleep(time.Millisecond)
+				ms = g.now().Sub(snowflakeEpoch).Milliseconds()
+				if ms < g.lastMs {
+					ms = g.lastMs + 1
+				}
+			}
+		}
+	} else {
+		g.sequence = 0
+	}
+	g.lastMs = ms
+
+	id := ms<<(snowflakeWorkerBits+snowflakeSequenceBits) |
+		g.workerID<<snowflakeSequenceBits |
+		g.sequence
+	return strconv.FormatInt(id, 10)
+}
+
+// incrementBytes 将大端字节序整数加1，溢出时返回false
+func incrementBytes(b []byte) bool {
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i]++
+		if b[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// fillRandom 使用加密安全随机数填充
+func fillRandom(b []byte) {
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("读取随机数失败: %v", err))
+	}
+}
+
+// encodeCrockford 将128位数据编码为26位Crockford Base32
+func encodeCrockford(raw [16]byte) string {
+	hi := binary.BigEndian.Uint64(raw[:8])
+	lo := binary.BigEndian.Uint64(raw[8:])
+
+	var out [26]byte
+	for i := 25; i >= 0; i-- {
+		out[i] = crockfordAlphabet[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
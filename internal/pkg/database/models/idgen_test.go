@@ -0,0 +1,167 @@
+package models
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// collectIDs 多协程并发生成ID
+func collectIDs(generator IDGenerator, workers, perWorker int) []string {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	ids := make([]string, 0, workers*perWorker)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			local := make([]string, 0, perWorker)
+			for i := 0; i < perWorker; i++ {
+				local = append(local, generator.NewID())
+			}
+			mu.Lock()
+			ids = append(ids, local...)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return ids
+}
+
+func assertUnique(t *testing.T, ids []string) {
+	t.Helper()
+	seen := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		_, dup := seen[id]
+		require.False(t, dup, "重复ID: %s", id)
+		seen[id] = struct{}{}
+	}
+}
+
+func TestNewIDGenerator(t *testing.T) {
+	for _, kind := range []string{"", "uuid", "ULID", "snowflake"} {
+		generator, err := NewIDGenerator(kind, 1)
+		require.NoError(t, err, kind)
+		assert.LessOrEqual(t, len(generator.NewID()), 36, "ID必须能存入char(36)列")
+	}
+
+	_, err := NewIDGenerator("random", 0)
+	assert.Error(t, err)
+	_, err = NewIDGenerator(IDGeneratorSnowflake, MaxSnowflakeWorkerID+1)
+	assert.Error(t, err)
+}
+
+func TestULIDGenerator(t *testing.T) {
+	t.Run("no collisions under concurrency", func(t *testing.T) {
+		assertUnique(t, collectIDs(NewULIDGenerator(), 16, 5000))
+	})
+
+	t.Run("monotonic within the same millisecond", func(t *testing.T) {
+		generator := NewULIDGenerator()
+		fixed := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+		generator.now = func() time.Time { return fixed }
+
+		ids := make([]string, 1000)
+		for i := range ids {
+			ids[i] = generator.NewID()
+			assert.Len(t, ids[i], 26)
+		}
+		assert.True(t, sort.StringsAreSorted(ids))
+		assertUnique(t, ids)
+	})
+
+	t.Run("sorted by time and survives clock rollback", func(t *testing.T) {
+		generator := NewULIDGenerator()
+		now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+		generator.now = func() time.Time { return now }
+
+		first := generator.NewID()
+		now = now.Add(time.Second)
+		second := generator.NewID()
+		now = now.Add(-time.Minute)
+		third := generator.NewID()
+
+		assert.Less(t, first, second)
+		assert.Less(t, second, third, "时钟回拨后仍保持递增")
+	})
+
+	t.Run("random part overflow", func(t *testing.T) {
+		generator := NewULIDGenerator()
+		fixed := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+		generator.now = func() time.Time { return fixed }
+
+		first := generator.NewID()
+		for i := range generator.lastRnd {
+			generator.lastRnd[i] = 0xff
+		}
+		second := generator.NewID()
+		assert.Less(t, first, second)
+		assert.Equal(t, uint64(fixed.UnixMilli())+1, generator.lastMs)
+	})
+}
+
+func TestSnowflakeGenerator(t *testing.T) {
+	t.Run("no collisions across workers", func(t *testing.T) {
+		var ids []string
+		for worker := int64(0); worker < 4; worker++ {
+			generator, err := NewSnowflakeGenerator(worker)
+			require.NoError(t, err)
+			ids = append(ids, collectIDs(generator, 8, 2000)...)
+		}
+		assertUnique(t, ids)
+	})
+
+	t.Run("sequence exhaustion waits for next millisecond", func(t *testing.T) {
+		generator, err := NewSnowflakeGenerator(3)
+		require.NoError(t, err)
+		now := snowflakeEpoch.Add(time.Hour)
+		generator.now = func() time.Time { return now }
+		generator.sleep = func(d time.Duration) { now = now.Add(d) }
+
+		var last int64
+		for i := 0; i < snowflakeSequenceMask+10; i++ {
+			id, err := strconv.ParseInt(generator.NewID(), 10, 64)
+			require.NoError(t, err)
+			require.Greater(t, id, last)
+			last = id
+		}
+		assert.Equal(t, int64(3), last>>snowflakeSequenceBits&MaxSnowflakeWorkerID)
+		assert.Equal(t, time.Hour.Milliseconds()+1, generator.lastMs)
+	})
+
+	t.Run("clock rollback", func(t *testing.T) {
+		generator, err := NewSnowflakeGenerator(0)
+		require.NoError(t, err)
+		now := snowflakeEpoch.Add(time.Hour)
+		generator.now = func() time.Time { return now }
+
+		first, _ := strconv.ParseInt(generator.NewID(), 10, 64)
+		now = now.Add(-time.Second)
+		second, _ := strconv.ParseInt(generator.NewID(), 10, 64)
+		assert.Greater(t, second, first)
+	})
+}
+
+func TestGenerateShareCode(t *testing.T) {
+	t.Cleanup(func() { SetIDGenerator(nil) })
+
+	SetIDGenerator(nil)
+	assert.Len(t, GenerateShareCode(), 8)
+	assert.Len(t, GenerateUUID(), 36)
+
+	SetIDGenerator(NewULIDGenerator())
+	first, second := GenerateShareCode(), GenerateShareCode()
+	assert.Len(t, first, 8, "ULID同一毫秒内递增，分享码仍使用随机字符串")
+	assert.NotEqual(t, first, second)
+	assert.Len(t, GenerateUUID(), 26)
+
+	snowflake, err := NewSnowflakeGenerator(1)
+	require.NoError(t, err)
+	SetIDGenerator(snowflake)
+	assert.Len(t, GenerateShareCode(), 8, "雪花ID可推算，分享码仍使用随机字符串")
+}
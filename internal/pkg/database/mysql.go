@@ -13,6 +13,7 @@ import (
 	"gorm.io/gorm/logger"
 
 	"cloudpan/internal/pkg/config"
+	"cloudpan/internal/pkg/tracing"
 )
 
 var (
@@ -81,6 +82,13 @@ func createDatabaseConnection(cfg config.MySQLConfig) (*gorm.DB, error) {
 		return nil, fmt.Errorf("failed to connect to MySQL: %w", err)
 	}
 
+	// 启用追踪时为SQL语句创建span，需在 tracing.Init 之后初始化数据库
+	if tracing.Enabled() {
+		if err := db.Use(TracingPlugin{}); err != nil {
+			return nil, fmt.Errorf("failed to register tracing plugin: %w", err)
+		}
+	}
+
 	return db, nil
 }
 
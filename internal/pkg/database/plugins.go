@@ -8,6 +8,8 @@ import (
 
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
+
+	"cloudpan/internal/pkg/indexing"
 )
 
 // 定义自定义类型作为context key以避免冲突
@@ -114,6 +116,39 @@ func (p *TracePlugin) Initialize(db *gorm.DB) error {
 	return nil
 }
 
+// IndexingPlugin 索引文档发布插件
+//
+// 模型钩子在事务内暂存索引文档，本插件在事务提交后统一发布，
+// 语句失败或回滚时暂存的文档会被丢弃
+type IndexingPlugin struct{}
+
+func (p *IndexingPlugin) Name() string {
+	return "indexing"
+}
+
+func (p *IndexingPlugin) Initialize(db *gorm.DB) error {
+	const after = "gorm:commit_or_rollback_transaction"
+	if err := db.Callback().Create().After(after).Register("indexing:flush_create", indexingFlush); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().After(after).Register("indexing:flush_update", indexingFlush); err != nil {
+		return err
+	}
+	if err := db.Callback().Delete().After(after).Register("indexing:flush_delete", indexingFlush); err != nil {
+		return err
+	}
+
+	log.Println("Indexing plugin initialized")
+	return nil
+}
+
+// 索引发布回调函数
+func indexingFlush(db *gorm.DB) {
+	if err := indexing.Flush(db); err != nil {
+		log.Printf("Indexing: failed to publish documents for table %s: %v", db.Statement.Table, err)
+	}
+}
+
 // 审计回调函数
 func auditCreate(db *gorm.DB) {
 	if db.Error != nil {
@@ -213,6 +248,7 @@ func GetDefaultPlugins() []Plugin {
 		&AuditPlugin{},
 		&MetricsPlugin{SlowQueryThreshold: 200 * time.Millisecond},
 		&TracePlugin{},
+		&IndexingPlugin{},
 	}
 }
 
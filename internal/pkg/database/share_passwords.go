@@ -0,0 +1,62 @@
+package database
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/utils"
+)
+
+// sharePasswordTable 分享表名
+const sharePasswordTable = "file_shares"
+
+// HashPlaintextSharePasswords 将历史遗留的明文分享密码批量替换为BCrypt哈希
+//
+// 直接读写原始列值，已是哈希的密码跳过，可重复执行；软删除的分享同样会被处理
+func HashPlaintextSharePasswords(db *gorm.DB, batchSize int) (*ReencryptStats, error) {
+	if batchSize <= 0 {
+		batchSize = defaultReencryptBatchSize
+	}
+
+	stats := &ReencryptStats{Table: sharePasswordTable}
+	var lastID uint
+	for {
+		type shareRow struct {
+			ID       uint
+			Password string
+		}
+		var batch []shareRow
+		err := db.Table(sharePasswordTable).
+			Select("id", "password").
+			Where("id > ? AND password IS NOT NULL AND password <> ''", lastID).
+			Order("id ASC").
+			Limit(batchSize).
+			Scan(&batch).Error
+		if err != nil {
+			return stats, fmt.Errorf("读取 %s 失败: %w", sharePasswordTable, err)
+		}
+		if len(batch) == 0 {
+			return stats, nil
+		}
+
+		for _, row := range batch {
+			lastID = row.ID
+			stats.Scanned++
+			if utils.IsPasswordHash(row.Password) {
+				continue
+			}
+
+			hashed, err := utils.HashPassword(row.Password)
+			if err != nil {
+				return stats, fmt.Errorf("哈希 %s#%d 密码失败: %w", sharePasswordTable, row.ID, err)
+			}
+			err = db.Table(sharePasswordTable).Where("id = ?", row.ID).
+				UpdateColumns(map[string]interface{}{"password": hashed, "has_password": true}).Error
+			if err != nil {
+				return stats, fmt.Errorf("更新 %s#%d 失败: %w", sharePasswordTable, row.ID, err)
+			}
+			stats.Updated++
+		}
+	}
+}
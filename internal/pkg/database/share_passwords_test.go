@@ -0,0 +1,61 @@
+package database
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/utils"
+)
+
+// sharePasswordTestRow 分享密码迁移测试模型
+type sharePasswordTestRow struct {
+	ID          uint `gorm:"primarykey"`
+	Password    *string
+	HasPassword bool
+}
+
+func (sharePasswordTestRow) TableName() string {
+	return sharePasswordTable
+}
+
+func TestHashPlaintextSharePasswords(t *testing.T) {
+	sqlDB, err := sql.Open("sqlite", ":memory:")
+	require.NoError(t, err)
+	db, err := gorm.Open(sqlite.Dialector{Conn: sqlDB}, &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&sharePasswordTestRow{}))
+
+	plain := "1234"
+	hashed, err := utils.HashPassword("abcd")
+	require.NoError(t, err)
+	empty := ""
+	rows := []*sharePasswordTestRow{
+		{Password: &plain, HasPassword: true},
+		{Password: &hashed, HasPassword: true},
+		{Password: &empty},
+		{},
+	}
+	require.NoError(t, db.Create(&rows).Error)
+
+	stats, err := HashPlaintextSharePasswords(db, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stats.Scanned)
+	assert.Equal(t, 1, stats.Updated)
+
+	var migrated sharePasswordTestRow
+	require.NoError(t, db.First(&migrated, rows[0].ID).Error)
+	assert.True(t, utils.VerifyPassword(*migrated.Password, plain))
+
+	var untouched sharePasswordTestRow
+	require.NoError(t, db.First(&untouched, rows[1].ID).Error)
+	assert.Equal(t, hashed, *untouched.Password)
+
+	stats, err = HashPlaintextSharePasswords(db, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 0, stats.Updated)
+}
@@ -0,0 +1,103 @@
+package database
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/tracing"
+)
+
+// tracingSpanKey 语句设置中保存span的键，语句结束时删除
+const tracingSpanKey = "cloudpan:tracing_span"
+
+// TracingPlugin GORM追踪插件
+//
+// 为增删改查、Row和Raw语句创建客户端span，记录表名、SQL(参数为占位符)和影响行数。
+// 只有通过 WithContext 传入请求上下文且所在链路已采样时才创建span，后台任务中没有上下文的查询不产生孤立的根span
+//
+// 使用示例：
+//
+//	if err := db.Use(database.TracingPlugin{}); err != nil {
+//		return err
+//	}
+type TracingPlugin struct{}
+
+// Name 插件名称
+func (TracingPlugin) Name() string {
+	return "cloudpan:tracing"
+}
+
+// Initialize 在各类语句的执行回调前后注册span的创建和结束
+func (p TracingPlugin) Initialize(db *gorm.DB) error {
+	callback := db.Callback()
+	return errors.Join(
+		callback.Create().Before("gorm:create").Register("tracing:before_create", p.before("create")),
+		callback.Create().After("gorm:create").Register("tracing:after_create", p.after),
+		callback.Query().Before("gorm:query").Register("tracing:before_query", p.before("query")),
+		callback.Query().After("gorm:query").Register("tracing:after_query", p.after),
+		callback.Update().Before("gorm:update").Register("tracing:before_update", p.before("update")),
+		callback.Update().After("gorm:update").Register("tracing:after_update", p.after),
+		callback.Delete().Before("gorm:delete").Register("tracing:before_delete", p.before("delete")),
+		callback.Delete().After("gorm:delete").Register("tracing:after_delete", p.after),
+		callback.Row().Before("gorm:row").Register("tracing:before_row", p.before("row")),
+		callback.Row().After("gorm:row").Register("tracing:after_row", p.after),
+		callback.Raw().Before("gorm:raw").Register("tracing:before_raw", p.before("raw")),
+		callback.Raw().After("gorm:raw").Register("tracing:after_raw", p.after),
+	)
+}
+
+// tracingState 语句执行期间的span和执行前的上下文
+type tracingState struct {
+	span   trace.Span
+	parent context.Context
+}
+
+// before 创建span并替换语句的上下文，语句内的嵌套调用(如关联保存)挂在该span下
+func (TracingPlugin) before(operation string) func(*gorm.DB) {
+	return func(tx *gorm.DB) {
+		if tx.Statement == nil || tx.Statement.Context == nil || !tracing.InTrace(tx.Statement.Context) {
+			return
+		}
+		parent := tx.Statement.Context
+		ctx, span := tracing.Tracer().Start(parent, "gorm."+operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(semconv.DBSystemKey.String(tx.Dialector.Name()), semconv.DBOperationName(operation)),
+		)
+		tx.Statement.Context = ctx
+		tx.Statement.Settings.Store(tracingSpanKey, &tracingState{span: span, parent: parent})
+	}
+}
+
+// after 记录语句信息并结束span，记录不存在不视为失败
+func (TracingPlugin) after(tx *gorm.DB) {
+	value, ok := tx.Statement.Settings.LoadAndDelete(tracingSpanKey)
+	if !ok {
+		return
+	}
+	state, ok := value.(*tracingState)
+	if !ok {
+		return
+	}
+	// 恢复执行前的上下文，同一会话中的后续语句仍挂在调用方的span下
+	tx.Statement.Context = state.parent
+	span := state.span
+	defer span.End()
+
+	if tx.Statement.Table != "" {
+		span.SetAttributes(semconv.DBCollectionName(tx.Statement.Table))
+	}
+	if sql := tx.Statement.SQL.String(); sql != "" {
+		span.SetAttributes(semconv.DBQueryText(sql))
+	}
+	span.SetAttributes(attribute.Int64("db.rows_affected", tx.Statement.RowsAffected))
+	if tx.Error != nil && !errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		span.RecordError(tx.Error)
+		span.SetStatus(codes.Error, tx.Error.Error())
+	}
+}
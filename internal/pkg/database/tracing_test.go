@@ -0,0 +1,66 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/tracing"
+)
+
+type tracedRecord struct {
+	ID   uint
+	Name string
+}
+
+func TestTracingPlugin(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(previous)
+
+	sqlDB, err := sql.Open("sqlite", ":memory:")
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	db, err := gorm.Open(sqlite.Dialector{Conn: sqlDB}, &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&tracedRecord{}))
+	require.NoError(t, db.Use(TracingPlugin{}))
+
+	// 不在链路中的查询不创建span
+	require.NoError(t, db.Create(&tracedRecord{Name: "background"}).Error)
+	assert.Empty(t, recorder.Ended())
+
+	ctx, request := tracing.Start(context.Background(), "request")
+	session := db.WithContext(ctx)
+	require.NoError(t, session.Create(&tracedRecord{Name: "traced"}).Error)
+	var found tracedRecord
+	err = session.Where("name = ?", "missing").First(&found).Error
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	err = session.Exec("INSERT INTO missing_table VALUES (1)").Error
+	assert.Error(t, err)
+	request.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 4)
+	create, query, raw := spans[0], spans[1], spans[2]
+	for _, span := range []sdktrace.ReadOnlySpan{create, query, raw} {
+		assert.Equal(t, request.SpanContext().SpanID(), span.Parent().SpanID(), span.Name())
+	}
+	assert.Equal(t, "gorm.create", create.Name())
+	assert.Contains(t, create.Attributes(), semconv.DBCollectionName("traced_records"))
+	assert.Equal(t, "gorm.query", query.Name())
+	assert.Equal(t, codes.Unset, query.Status().Code, "记录不存在不视为失败")
+	assert.Equal(t, "gorm.raw", raw.Name())
+	assert.Equal(t, codes.Error, raw.Status().Code)
+}
@@ -0,0 +1,71 @@
+package database
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// TxManager 跨仓储的事务管理器(工作单元)
+//
+// 多个服务或仓储的写入需要同时成功或同时失败时(如重置密码并使验证码失效)，
+// 在 WithTransaction 中执行这些调用：
+// 1. 事务通过 fn 的 ctx 传递，仓储使用 Conn(ctx, db) 取得连接，自动加入当前事务
+// 2. fn 返回错误或panic时回滚，否则提交
+// 3. 已在事务中时再次调用 WithTransaction 创建保存点，内层失败只回滚到保存点
+//
+// fn 的 ctx 只能在 fn 返回前使用，不要传给在事务结束后仍会执行的goroutine
+//
+// 使用示例：
+//
+//	txManager := database.NewTxManager(database.GetDB())
+//	err := txManager.WithTransaction(ctx, func(ctx context.Context) error {
+//		if err := userService.UpdatePassword(ctx, userID, hash); err != nil {
+//			return err
+//		}
+//		return verificationService.CompletePasswordReset(ctx, codeID)
+//	})
+type TxManager interface {
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// txManager 基于GORM的事务管理器实现
+type txManager struct {
+	db *gorm.DB
+}
+
+// NewTxManager 创建事务管理器
+func NewTxManager(db *gorm.DB) TxManager {
+	return &txManager{db: db}
+}
+
+// WithTransaction 在事务中执行fn，ctx已绑定事务时创建保存点
+func (m *txManager) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return Conn(ctx, m.db).Transaction(func(tx *gorm.DB) error {
+		return fn(ContextWithTx(ctx, tx))
+	})
+}
+
+// txContextKey 事务在context中的键
+type txContextKey struct{}
+
+// ContextWithTx 返回绑定事务的context
+func ContextWithTx(ctx context.Context, tx *gorm.DB) context.Context {
+	return context.WithValue(ctx, txContextKey{}, tx)
+}
+
+// TxFromContext 返回context绑定的事务，没有时返回nil
+func TxFromContext(ctx context.Context) *gorm.DB {
+	tx, _ := ctx.Value(txContextKey{}).(*gorm.DB)
+	return tx
+}
+
+// Conn 返回执行数据库操作的连接：ctx绑定了事务时使用该事务，否则使用db，均设置ctx
+//
+// 仓储用 Conn(ctx, r.db) 代替 r.db.WithContext(ctx)，即可在调用方的事务中执行
+func Conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx := TxFromContext(ctx); tx != nil {
+		return tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
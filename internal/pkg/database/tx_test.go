@@ -0,0 +1,91 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type txTestRecord struct {
+	ID   uint `gorm:"primaryKey"`
+	Name string
+}
+
+// txTestRepository 按仓储的方式通过 Conn 访问数据库
+type txTestRepository struct {
+	db *gorm.DB
+}
+
+func (r *txTestRepository) Create(ctx context.Context, name string) error {
+	return Conn(ctx, r.db).Create(&txTestRecord{Name: name}).Error
+}
+
+func (r *txTestRepository) Count(ctx context.Context) int64 {
+	var count int64
+	Conn(ctx, r.db).Model(&txTestRecord{}).Count(&count)
+	return count
+}
+
+func setupTxTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	sqlDB, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "tx.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+	db, err := gorm.Open(sqlite.Dialector{Conn: sqlDB}, &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&txTestRecord{}))
+	return db
+}
+
+func TestTxManager_WithTransaction(t *testing.T) {
+	ctx := context.Background()
+	db := setupTxTestDB(t)
+	repo := &txTestRepository{db: db}
+	manager := NewTxManager(db)
+
+	t.Run("commit", func(t *testing.T) {
+		err := manager.WithTransaction(ctx, func(ctx context.Context) error {
+			require.NotNil(t, TxFromContext(ctx))
+			if err := repo.Create(ctx, "a"); err != nil {
+				return err
+			}
+			assert.Equal(t, int64(1), repo.Count(ctx), "事务内可见未提交的写入")
+			return repo.Create(ctx, "b")
+		})
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), repo.Count(ctx))
+	})
+
+	t.Run("rollback on error", func(t *testing.T) {
+		errStop := errors.New("stop")
+		err := manager.WithTransaction(ctx, func(ctx context.Context) error {
+			require.NoError(t, repo.Create(ctx, "c"))
+			return errStop
+		})
+		assert.ErrorIs(t, err, errStop)
+		assert.Equal(t, int64(2), repo.Count(ctx), "两个仓储调用一起回滚")
+	})
+
+	t.Run("nested savepoint", func(t *testing.T) {
+		err := manager.WithTransaction(ctx, func(ctx context.Context) error {
+			require.NoError(t, repo.Create(ctx, "d"))
+			inner := manager.WithTransaction(ctx, func(ctx context.Context) error {
+				require.NoError(t, repo.Create(ctx, "e"))
+				return errors.New("inner")
+			})
+			assert.Error(t, inner)
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), repo.Count(ctx), "内层失败只回滚到保存点")
+	})
+
+	assert.Nil(t, TxFromContext(ctx), "未绑定事务时使用原连接")
+}
@@ -30,6 +30,9 @@ type EmailConfig struct {
 	ResetTokenTTL       string     `mapstructure:"reset_token_ttl" json:"reset_token_ttl"`             // 重置令牌有效期
 	TemplateDir         string     `mapstructure:"template_dir" json:"template_dir"`                   // 模板目录
 	DefaultLanguage     string     `mapstructure:"default_language" json:"default_language"`           // 默认语言
+
+	Routes  []RouteConfig  `mapstructure:"routes" json:"routes"`   // 按收件人域名选择的发送线路，未匹配的收件人使用上面的SMTP配置
+	Routing RoutingOptions `mapstructure:"routing" json:"routing"` // 线路投递统计和自动降级参数
 }
 
 // GetRetryInterval 获取重试间隔时间
@@ -100,6 +103,16 @@ func (c *EmailConfig) Validate() error {
 	if c.MaxRetries < 0 {
 		return fmt.Errorf("max_retries cannot be negative")
 	}
+	names := make(map[string]bool, len(c.Routes))
+	for i := range c.Routes {
+		if err := c.Routes[i].validate(); err != nil {
+			return err
+		}
+		if names[c.Routes[i].Name] {
+			return fmt.Errorf("duplicate route name: %s", c.Routes[i].Name)
+		}
+		names[c.Routes[i].Name] = true
+	}
 	if c.PoolSize <= 0 {
 		c.PoolSize = 10 // 默认连接池大小
 	}
@@ -157,10 +170,14 @@ type EmailTemplate struct {
 	Subject     string            `json:"subject"`     // 邮件主题
 	HTMLBody    string            `json:"html_body"`   // HTML内容
 	TextBody    string            `json:"text_body"`   // 纯文本内容
-	Variables   map[string]string `json:"variables"`   // 模板变量
+	Variables   map[string]string `json:"variables"`   // 模板可以使用的变量及说明，为空时不限制
 	Language    string            `json:"language"`    // 语言
 	IsActive    bool              `json:"is_active"`   // 是否激活
 	Description string            `json:"description"` // 模板描述
+
+	Source    string     `json:"source,omitempty"`     // 模板来源：default(内置)、file(模板目录)、database(管理员修改)
+	UpdatedBy uint       `json:"updated_by,omitempty"` // 修改模板的管理员ID，只有database来源有值
+	UpdatedAt *time.Time `json:"updated_at,omitempty"` // 管理员修改的时间
 }
 
 // TemplateType 邮件模板类型常量
@@ -172,6 +189,9 @@ const (
 	TemplateSecurityAlert    = "security_alert"    // 安全警告模板
 	TemplateTeamInvitation   = "team_invitation"   // 团队邀请模板
 	TemplateFileShared       = "file_shared"       // 文件分享模板
+
+	TemplateForcedPasswordReset = "forced_password_reset" // 管理员强制重置密码模板
+	TemplateInactivityWarning   = "inactivity_warning"    // 长期未登录账户处置提醒模板
 )
 
 // EmailQueue 邮件队列项
@@ -185,6 +205,7 @@ type EmailQueue struct {
 	TextBody    string                 `json:"text_body"`
 	Template    string                 `json:"template"`
 	Variables   map[string]interface{} `json:"variables"`
+	Language    string                 `json:"language,omitempty"` // 收件人语言，为空时使用默认语言的模板
 	Priority    int                    `json:"priority"`
 	Attempts    int                    `json:"attempts"`
 	MaxAttempts int                    `json:"max_attempts"`
@@ -193,6 +214,8 @@ type EmailQueue struct {
 	UpdatedAt   time.Time              `json:"updated_at"`
 	Status      string                 `json:"status"`
 	ErrorMsg    string                 `json:"error_msg"`
+	Errors      []string               `json:"errors,omitempty"`    // 每次发送失败的原因，按失败顺序
+	FailedAt    *time.Time             `json:"failed_at,omitempty"` // 达到最大重试次数进入死信队列的时间
 }
 
 // 邮件队列状态常量
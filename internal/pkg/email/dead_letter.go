@@ -0,0 +1,364 @@
+package email
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// 死信队列在Redis中的键
+const (
+	deadLetterItemsKey = "queue:email:dead"       // 哈希：邮件ID -> 邮件JSON
+	deadLetterIndexKey = "queue:email:dead:index" // 有序集合：邮件ID，分值为进入死信队列的时间(毫秒)
+)
+
+// 死信队列告警级别
+const (
+	DeadLetterAlertNone     = ""         // 未达到告警阈值
+	DeadLetterAlertWarning  = "warning"  // 达到警告阈值
+	DeadLetterAlertCritical = "critical" // 达到严重阈值
+)
+
+// defaultDeadLetterAlertCooldown 同一级别告警的默认最短间隔
+const defaultDeadLetterAlertCooldown = 15 * time.Minute
+
+// DeadLetterStore 邮件死信存储
+//
+// 保存达到最大重试次数仍发送失败的邮件，供管理员查看失败原因、重新发送或清除。
+// 多实例部署时应使用 RedisDeadLetterStore，服务重启后死信不丢失
+//
+// 使用示例：
+//
+//	store := email.NewRedisDeadLetterStore(cache.RedisClient)
+//	err := store.Add(ctx, item)
+//	items, total, err := store.List(ctx, 0, 20)
+//	taken, err := store.Take(ctx, []string{item.ID})
+type DeadLetterStore interface {
+	// Add 保存一封发送失败的邮件，ID相同时覆盖
+	Add(ctx context.Context, item *EmailQueue) error
+	// List 按进入死信队列的时间倒序返回邮件和总数，limit<=0时返回offset之后的全部
+	List(ctx context.Context, offset, limit int) ([]*EmailQueue, int64, error)
+	// Take 取出并删除指定的邮件，不存在的ID被忽略；多实例同时取出同一封邮件时只有一个成功
+	Take(ctx context.Context, ids []string) ([]*EmailQueue, error)
+	// Remove 删除指定的邮件，返回实际删除的数量
+	Remove(ctx context.Context, ids []string) (int64, error)
+	// Purge 清空死信队列，返回删除的数量
+	Purge(ctx context.Context) (int64, error)
+	// Count 返回死信数量
+	Count(ctx context.Context) (int64, error)
+}
+
+// MemoryDeadLetterStore 进程内死信存储，用于单实例部署和测试，重启后丢失
+type MemoryDeadLetterStore struct {
+	mu    sync.Mutex
+	items map[string]*EmailQueue
+}
+
+// NewMemoryDeadLetterStore 创建进程内死信存储
+func NewMemoryDeadLetterStore() *MemoryDeadLetterStore {
+	return &MemoryDeadLetterStore{items: make(map[string]*EmailQueue)}
+}
+
+// Add 保存一封发送失败的邮件
+func (s *MemoryDeadLetterStore) Add(_ context.Context, item *EmailQueue) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items[item.ID] = item
+	return nil
+}
+
+// List 按进入死信队列的时间倒序返回邮件
+func (s *MemoryDeadLetterStore) List(_ context.Context, offset, limit int) ([]*EmailQueue, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	items := make([]*EmailQueue, 0, len(s.items))
+	for _, item := range s.items {
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		ti, tj := deadLetterTime(items[i]), deadLetterTime(items[j])
+		if !ti.Equal(tj) {
+			return ti.After(tj)
+		}
+		return items[i].ID < items[j].ID
+	})
+
+	total := int64(len(items))
+	if offset >= len(items) {
+		return []*EmailQueue{}, total, nil
+	}
+	items = items[offset:]
+	if limit > 0 && limit < len(items) {
+		items = items[:limit]
+	}
+	return items, total, nil
+}
+
+// Take 取出并删除指定的邮件
+func (s *MemoryDeadLetterStore) Take(_ context.Context, ids []string) ([]*EmailQueue, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	taken := make([]*EmailQueue, 0, len(ids))
+	for _, id := range ids {
+		if item, ok := s.items[id]; ok {
+			delete(s.items, id)
+			taken = append(taken, item)
+		}
+	}
+	return taken, nil
+}
+
+// Remove 删除指定的邮件
+func (s *MemoryDeadLetterStore) Remove(_ context.Context, ids []string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var removed int64
+	for _, id := range ids {
+		if _, ok := s.items[id]; ok {
+			delete(s.items, id)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// Purge 清空死信队列
+func (s *MemoryDeadLetterStore) Purge(_ context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	removed := int64(len(s.items))
+	s.items = make(map[string]*EmailQueue)
+	return removed, nil
+}
+
+// Count 返回死信数量
+func (s *MemoryDeadLetterStore) Count(_ context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return int64(len(s.items)), nil
+}
+
+// RedisDeadLetterStore 基于Redis的死信存储，多实例共享且服务重启后不丢失
+//
+// 邮件JSON保存在哈希中，另用有序集合按进入死信队列的时间排序
+type RedisDeadLetterStore struct {
+	client *redis.Client
+}
+
+// NewRedisDeadLetterStore 创建Redis死信存储
+func NewRedisDeadLetterStore(client *redis.Client) *RedisDeadLetterStore {
+	return &RedisDeadLetterStore{client: client}
+}
+
+// Add 保存一封发送失败的邮件
+func (s *RedisDeadLetterStore) Add(ctx context.Context, item *EmailQueue) error {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return fmt.Errorf("序列化死信邮件失败: %w", err)
+	}
+	pipe := s.client.TxPipeline()
+	pipe.HSet(ctx, deadLetterItemsKey, item.ID, data)
+	pipe.ZAdd(ctx, deadLetterIndexKey, &redis.Z{Score: float64(deadLetterTime(item).UnixMilli()), Member: item.ID})
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("保存死信邮件失败: %w", err)
+	}
+	return nil
+}
+
+// List 按进入死信队列的时间倒序返回邮件
+func (s *RedisDeadLetterStore) List(ctx context.Context, offset, limit int) ([]*EmailQueue, int64, error) {
+	total, err := s.client.ZCard(ctx, deadLetterIndexKey).Result()
+	if err != nil {
+		return nil, 0, fmt.Errorf("读取死信数量失败: %w", err)
+	}
+
+	stop := int64(-1)
+	if limit > 0 {
+		stop = int64(offset + limit - 1)
+	}
+	ids, err := s.client.ZRevRange(ctx, deadLetterIndexKey, int64(offset), stop).Result()
+	if err != nil {
+		return nil, 0, fmt.Errorf("读取死信队列失败: %w", err)
+	}
+	items, err := s.load(ctx, ids)
+	if err != nil {
+		return nil, 0, err
+	}
+	return items, total, nil
+}
+
+// Take 取出并删除指定的邮件，以HDEL的结果判断本实例是否取到
+func (s *RedisDeadLetterStore) Take(ctx context.Context, ids []string) ([]*EmailQueue, error) {
+	items, err := s.load(ctx, ids)
+	if err != nil || len(items) == 0 {
+		return items, err
+	}
+
+	pipe := s.client.TxPipeline()
+	deletes := make([]*redis.IntCmd, len(items))
+	for i, item := range items {
+		deletes[i] = pipe.HDel(ctx, deadLetterItemsKey, item.ID)
+		pipe.ZRem(ctx, deadLetterIndexKey, item.ID)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("取出死信邮件失败: %w", err)
+	}
+
+	taken := make([]*EmailQueue, 0, len(items))
+	for i, item := range items {
+		if deletes[i].Val() > 0 {
+			taken = append(taken, item)
+		}
+	}
+	return taken, nil
+}
+
+// Remove 删除指定的邮件
+func (s *RedisDeadLetterStore) Remove(ctx context.Context, ids []string) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	members := make([]interface{}, len(ids))
+	for i, id := range ids {
+		members[i] = id
+	}
+
+	pipe := s.client.TxPipeline()
+	removed := pipe.HDel(ctx, deadLetterItemsKey, ids...)
+	pipe.ZRem(ctx, deadLetterIndexKey, members...)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("删除死信邮件失败: %w", err)
+	}
+	return removed.Val(), nil
+}
+
+// Purge 清空死信队列
+func (s *RedisDeadLetterStore) Purge(ctx context.Context) (int64, error) {
+	pipe := s.client.TxPipeline()
+	count := pipe.HLen(ctx, deadLetterItemsKey)
+	pipe.Del(ctx, deadLetterItemsKey, deadLetterIndexKey)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("清空死信队列失败: %w", err)
+	}
+	return count.Val(), nil
+}
+
+// Count 返回死信数量
+func (s *RedisDeadLetterStore) Count(ctx context.Context) (int64, error) {
+	count, err := s.client.HLen(ctx, deadLetterItemsKey).Result()
+	if err != nil {
+		return 0, fmt.Errorf("读取死信数量失败: %w", err)
+	}
+	return count, nil
+}
+
+// load 按ID顺序读取邮件，已被删除的ID被忽略
+func (s *RedisDeadLetterStore) load(ctx context.Context, ids []string) ([]*EmailQueue, error) {
+	if len(ids) == 0 {
+		return []*EmailQueue{}, nil
+	}
+	values, err := s.client.HMGet(ctx, deadLetterItemsKey, ids...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("读取死信邮件失败: %w", err)
+	}
+
+	items := make([]*EmailQueue, 0, len(values))
+	for _, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			continue
+		}
+		var item EmailQueue
+		if err := json.Unmarshal([]byte(data), &item); err != nil {
+			continue
+		}
+		items = append(items, &item)
+	}
+	return items, nil
+}
+
+// deadLetterTime 返回邮件进入死信队列的时间，旧数据没有记录时使用最后更新时间
+func deadLetterTime(item *EmailQueue) time.Time {
+	if item.FailedAt != nil {
+		return *item.FailedAt
+	}
+	return item.UpdatedAt
+}
+
+// DeadLetterAlertOptions 死信数量告警选项
+type DeadLetterAlertOptions struct {
+	WarningThreshold  int64         // 死信数达到该值时触发警告，0表示不告警
+	CriticalThreshold int64         // 死信数达到该值时触发严重告警，0表示不告警
+	Cooldown          time.Duration // 同一级别告警的最短间隔，为0时使用15分钟
+}
+
+// DeadLetterAlerter 死信数量告警器
+//
+// 每次有邮件进入死信队列后检查死信总数，达到阈值时调用通知函数。
+// 同一级别在冷却时间内只通知一次，级别升高时立即通知
+type DeadLetterAlerter struct {
+	options DeadLetterAlertOptions
+	notify  func(level string, size int64)
+	now     func() time.Time
+
+	mu        sync.Mutex
+	lastAlert map[string]time.Time
+}
+
+// NewDeadLetterAlerter 创建死信数量告警器，notify在发送失败的处理协程中同步调用
+func NewDeadLetterAlerter(options DeadLetterAlertOptions, notify func(level string, size int64)) *DeadLetterAlerter {
+	if options.Cooldown <= 0 {
+		options.Cooldown = defaultDeadLetterAlertCooldown
+	}
+	return &DeadLetterAlerter{
+		options:   options,
+		notify:    notify,
+		now:       time.Now,
+		lastAlert: make(map[string]time.Time),
+	}
+}
+
+// Level 返回死信数量对应的告警级别
+func (a *DeadLetterAlerter) Level(size int64) string {
+	switch {
+	case a.options.CriticalThreshold > 0 && size >= a.options.CriticalThreshold:
+		return DeadLetterAlertCritical
+	case a.options.WarningThreshold > 0 && size >= a.options.WarningThreshold:
+		return DeadLetterAlertWarning
+	default:
+		return DeadLetterAlertNone
+	}
+}
+
+// Check 检查死信数量，达到阈值且不在冷却时间内时通知，返回是否通知
+func (a *DeadLetterAlerter) Check(size int64) bool {
+	level := a.Level(size)
+	if level == DeadLetterAlertNone || a.notify == nil {
+		return false
+	}
+
+	a.mu.Lock()
+	now := a.now()
+	if last, ok := a.lastAlert[level]; ok && now.Sub(last) < a.options.Cooldown {
+		a.mu.Unlock()
+		return false
+	}
+	a.lastAlert[level] = now
+	a.mu.Unlock()
+
+	a.notify(level, size)
+	return true
+}
+
+// Options 返回告警选项
+func (a *DeadLetterAlerter) Options() DeadLetterAlertOptions {
+	return a.options
+}
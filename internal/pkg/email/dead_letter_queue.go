@@ -0,0 +1,162 @@
+package email
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// DeadLetterQueue 邮件死信队列管理接口，由 NewEmailService 创建的服务实现
+//
+// 使用示例：
+//
+//	queue := email.GetGlobalDeadLetterQueue()
+//	items, total, err := queue.ListDeadLetters(ctx, 0, 20)
+//	retried, err := queue.RetryDeadLetters(ctx, []string{items[0].ID})
+//	removed, err := queue.PurgeDeadLetters(ctx, nil)
+type DeadLetterQueue interface {
+	// ListDeadLetters 按进入死信队列的时间倒序分页返回发送失败的邮件和总数
+	ListDeadLetters(ctx context.Context, offset, limit int) ([]*EmailQueue, int64, error)
+	// RetryDeadLetters 将指定的死信重置重试次数后重新入队，返回重新入队的邮件ID。
+	// 队列已满时未入队的邮件放回死信队列并返回 ErrQueueFull
+	RetryDeadLetters(ctx context.Context, ids []string) ([]string, error)
+	// PurgeDeadLetters 删除指定的死信，ids为空时清空死信队列，返回删除的数量
+	PurgeDeadLetters(ctx context.Context, ids []string) (int64, error)
+	// ExportDeadLetters 返回全部死信，按进入死信队列的时间倒序
+	ExportDeadLetters(ctx context.Context) ([]*EmailQueue, error)
+	// DeadLetterStatus 返回队列和死信数量以及当前告警级别
+	DeadLetterStatus(ctx context.Context) (*DeadLetterStatus, error)
+}
+
+// DeadLetterStatus 邮件队列和死信队列状态
+type DeadLetterStatus struct {
+	Pending           int    `json:"pending"`                      // 等待发送的邮件数
+	Capacity          int    `json:"capacity"`                     // 队列容量
+	DeadLetters       int64  `json:"dead_letters"`                 // 死信数量
+	AlertLevel        string `json:"alert_level,omitempty"`        // 当前告警级别：warning、critical，未达到阈值时为空
+	WarningThreshold  int64  `json:"warning_threshold,omitempty"`  // 警告阈值
+	CriticalThreshold int64  `json:"critical_threshold,omitempty"` // 严重阈值
+}
+
+// ListDeadLetters 分页返回死信
+func (s *emailService) ListDeadLetters(ctx context.Context, offset, limit int) ([]*EmailQueue, int64, error) {
+	return s.deadLetterStore().List(ctx, offset, limit)
+}
+
+// RetryDeadLetters 将指定的死信重新入队
+func (s *emailService) RetryDeadLetters(ctx context.Context, ids []string) ([]string, error) {
+	s.mu.RLock()
+	running := s.isRunning
+	s.mu.RUnlock()
+	if !running {
+		return nil, ErrServiceNotRunning
+	}
+
+	store := s.deadLetterStore()
+	items, err := store.Take(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	retried := make([]string, 0, len(items))
+	for i, item := range items {
+		requeued := *item
+		requeued.ResetRetry()
+		requeued.FailedAt = nil
+		if err := s.QueueEmail(&requeued); err != nil {
+			// 未能入队的邮件原样放回死信队列
+			for _, rest := range items[i:] {
+				if addErr := store.Add(ctx, rest); addErr != nil {
+					log.Printf("Failed to restore dead letter email %s: %v", rest.ID, addErr)
+				}
+			}
+			return retried, err
+		}
+		retried = append(retried, item.ID)
+	}
+	return retried, nil
+}
+
+// PurgeDeadLetters 删除指定的死信或清空死信队列
+func (s *emailService) PurgeDeadLetters(ctx context.Context, ids []string) (int64, error) {
+	if len(ids) == 0 {
+		return s.deadLetterStore().Purge(ctx)
+	}
+	return s.deadLetterStore().Remove(ctx, ids)
+}
+
+// ExportDeadLetters 返回全部死信
+func (s *emailService) ExportDeadLetters(ctx context.Context) ([]*EmailQueue, error) {
+	items, _, err := s.deadLetterStore().List(ctx, 0, 0)
+	return items, err
+}
+
+// DeadLetterStatus 返回队列和死信状态
+func (s *emailService) DeadLetterStatus(ctx context.Context) (*DeadLetterStatus, error) {
+	count, err := s.deadLetterStore().Count(ctx)
+	if err != nil {
+		return nil, err
+	}
+	status := &DeadLetterStatus{
+		Pending:     len(s.queue),
+		Capacity:    cap(s.queue),
+		DeadLetters: count,
+	}
+	if alerter := s.deadLetterAlerter(); alerter != nil {
+		options := alerter.Options()
+		status.AlertLevel = alerter.Level(count)
+		status.WarningThreshold = options.WarningThreshold
+		status.CriticalThreshold = options.CriticalThreshold
+	}
+	return status, nil
+}
+
+// setDeadLetters 设置死信存储和告警器，store为nil时保留当前存储
+func (s *emailService) setDeadLetters(store DeadLetterStore, alerter *DeadLetterAlerter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if store != nil {
+		s.deadLetters = store
+	}
+	s.alerter = alerter
+}
+
+// deadLetterStore 返回当前死信存储
+func (s *emailService) deadLetterStore() DeadLetterStore {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.deadLetters
+}
+
+// deadLetterAlerter 返回当前死信告警器
+func (s *emailService) deadLetterAlerter() *DeadLetterAlerter {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.alerter
+}
+
+// moveToDeadLetter 将达到最大重试次数的邮件写入死信存储，并检查死信数量是否达到告警阈值
+func (s *emailService) moveToDeadLetter(emailItem *EmailQueue) {
+	now := time.Now()
+	emailItem.FailedAt = &now
+
+	ctx, cancel := context.WithTimeout(context.Background(), deadLetterTimeout)
+	defer cancel()
+
+	store := s.deadLetterStore()
+	if err := store.Add(ctx, emailItem); err != nil {
+		log.Printf("Failed to save dead letter email %s: %v", emailItem.ID, err)
+		return
+	}
+
+	alerter := s.deadLetterAlerter()
+	if alerter == nil {
+		return
+	}
+	count, err := store.Count(ctx)
+	if err != nil {
+		log.Printf("Failed to count dead letter emails: %v", err)
+		return
+	}
+	alerter.Check(count)
+}
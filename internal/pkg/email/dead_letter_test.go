@@ -0,0 +1,140 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func deadLetter(id string, failedAt time.Time) *EmailQueue {
+	return &EmailQueue{
+		ID:          id,
+		To:          []string{id + "@example.com"},
+		Subject:     "subject " + id,
+		Attempts:    3,
+		MaxAttempts: 3,
+		Status:      EmailStatusFailed,
+		ErrorMsg:    "dial tcp: timeout",
+		Errors:      []string{"dial tcp: timeout"},
+		FailedAt:    &failedAt,
+	}
+}
+
+func TestMemoryDeadLetterStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryDeadLetterStore()
+	base := time.Date(2026, 5, 1, 8, 0, 0, 0, time.UTC)
+	for i, id := range []string{"a", "b", "c"} {
+		require.NoError(t, store.Add(ctx, deadLetter(id, base.Add(time.Duration(i)*time.Minute))))
+	}
+
+	items, total, err := store.List(ctx, 0, 2)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	require.Len(t, items, 2)
+	assert.Equal(t, "c", items[0].ID) // 最近失败的在前
+	assert.Equal(t, "b", items[1].ID)
+
+	items, _, err = store.List(ctx, 2, 0)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "a", items[0].ID)
+
+	taken, err := store.Take(ctx, []string{"a", "missing"})
+	require.NoError(t, err)
+	require.Len(t, taken, 1)
+	assert.Equal(t, "a", taken[0].ID)
+	taken, err = store.Take(ctx, []string{"a"})
+	require.NoError(t, err)
+	assert.Empty(t, taken) // 已被取出
+
+	removed, err := store.Remove(ctx, []string{"b", "missing"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), removed)
+
+	removed, err = store.Purge(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), removed)
+	count, err := store.Count(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+func TestDeadLetterAlerter(t *testing.T) {
+	now := time.Date(2026, 5, 1, 8, 0, 0, 0, time.UTC)
+	var alerts []string
+	alerter := NewDeadLetterAlerter(DeadLetterAlertOptions{WarningThreshold: 2, CriticalThreshold: 4, Cooldown: time.Minute},
+		func(level string, size int64) { alerts = append(alerts, level) })
+	alerter.now = func() time.Time { return now }
+
+	assert.Equal(t, DeadLetterAlertNone, alerter.Level(1))
+	assert.False(t, alerter.Check(1))
+
+	assert.True(t, alerter.Check(2))
+	assert.False(t, alerter.Check(3)) // 冷却时间内不重复通知
+	assert.True(t, alerter.Check(4))  // 级别升高立即通知
+
+	now = now.Add(time.Minute)
+	assert.True(t, alerter.Check(5))
+	assert.Equal(t, []string{DeadLetterAlertWarning, DeadLetterAlertCritical, DeadLetterAlertCritical}, alerts)
+
+	disabled := NewDeadLetterAlerter(DeadLetterAlertOptions{}, func(string, int64) { t.Fatal("不应告警") })
+	assert.False(t, disabled.Check(1000))
+}
+
+func TestEmailService_DeadLetters(t *testing.T) {
+	ctx := context.Background()
+	service := NewEmailService(nil).(*emailService)
+	var alerted int64
+	service.setDeadLetters(nil, NewDeadLetterAlerter(DeadLetterAlertOptions{WarningThreshold: 1},
+		func(level string, size int64) { alerted = size }))
+
+	item := deadLetter("x", time.Now())
+	item.FailedAt = nil
+	service.moveToDeadLetter(item)
+	assert.NotNil(t, item.FailedAt)
+	assert.Equal(t, int64(1), alerted)
+
+	status, err := service.DeadLetterStatus(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), status.DeadLetters)
+	assert.Equal(t, DeadLetterAlertWarning, status.AlertLevel)
+	queueStatus, err := service.GetQueueStatus()
+	require.NoError(t, err)
+	assert.Equal(t, 1, queueStatus["dead_letter"])
+
+	// 服务未启动时不能重新发送
+	_, err = service.RetryDeadLetters(ctx, []string{"x"})
+	assert.True(t, errors.Is(err, ErrServiceNotRunning))
+
+	service.isRunning = true
+	retried, err := service.RetryDeadLetters(ctx, []string{"x", "missing"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"x"}, retried)
+	requeued := <-service.queue
+	assert.Equal(t, 0, requeued.Attempts)
+	assert.Equal(t, EmailStatusPending, requeued.Status)
+	assert.Nil(t, requeued.FailedAt)
+	assert.Equal(t, []string{"dial tcp: timeout"}, requeued.Errors) // 保留历史失败原因
+
+	// 队列已满时原样放回死信队列
+	require.NoError(t, service.deadLetters.Add(ctx, deadLetter("y", time.Now())))
+	for len(service.queue) < cap(service.queue) {
+		service.queue <- &EmailQueue{}
+	}
+	retried, err = service.RetryDeadLetters(ctx, []string{"y"})
+	assert.True(t, errors.Is(err, ErrQueueFull))
+	assert.Empty(t, retried)
+	items, err := service.ExportDeadLetters(ctx)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, 3, items[0].Attempts)
+
+	removed, err := service.PurgeDeadLetters(ctx, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), removed)
+}
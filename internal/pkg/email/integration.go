@@ -13,6 +13,10 @@ type EmailManager struct {
 	config  *EmailConfig
 	mu      sync.RWMutex
 	started bool
+
+	deadLetters DeadLetterStore
+	alerter     *DeadLetterAlerter
+	templates   TemplateStore
 }
 
 // NewEmailManager 创建邮件管理器
@@ -32,7 +36,7 @@ func (m *EmailManager) Initialize() error {
 	}
 
 	// 创建邮件服务
-	m.service = NewEmailService(m.config)
+	m.service = m.newService()
 
 	log.Println("Email service initialized successfully")
 	return nil
@@ -120,12 +124,86 @@ func (m *EmailManager) UpdateConfig(config *EmailConfig) error {
 	m.config = config
 	if m.service != nil {
 		// 重新创建服务实例
-		m.service = NewEmailService(config)
+		m.service = m.newService()
 	}
 
 	return nil
 }
 
+// SetDeadLetterStore 设置死信存储和死信数量告警器，对已创建和之后重新创建的服务都生效
+//
+// store为nil时使用进程内存储，alerter为nil时不告警
+func (m *EmailManager) SetDeadLetterStore(store DeadLetterStore, alerter *DeadLetterAlerter) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.deadLetters = store
+	m.alerter = alerter
+	if svc, ok := m.service.(*emailService); ok {
+		svc.setDeadLetters(store, alerter)
+	}
+}
+
+// GetDeadLetterQueue 获取死信队列管理接口，服务未初始化时返回nil
+func (m *EmailManager) GetDeadLetterQueue() DeadLetterQueue {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	queue, ok := m.service.(DeadLetterQueue)
+	if !ok {
+		return nil
+	}
+	return queue
+}
+
+// GetRouteMonitor 获取发送线路监控接口，服务未初始化或没有配置发送线路时返回nil
+func (m *EmailManager) GetRouteMonitor() RouteMonitor {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	svc, ok := m.service.(*emailService)
+	if !ok || svc.router == nil {
+		return nil
+	}
+	return svc
+}
+
+// SetTemplateStore 设置管理员修改的模板存储，对已创建和之后重新创建的服务都生效；需在启动服务前调用，
+// 否则在下次发送模板邮件时加载
+//
+// store为nil时不能在线修改模板
+func (m *EmailManager) SetTemplateStore(store TemplateStore) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.templates = store
+	if svc, ok := m.service.(*emailService); ok {
+		svc.setTemplateStore(store)
+	}
+}
+
+// GetTemplateManager 获取模板管理接口，服务未初始化时返回nil
+func (m *EmailManager) GetTemplateManager() TemplateManager {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	manager, ok := m.service.(TemplateManager)
+	if !ok {
+		return nil
+	}
+	return manager
+}
+
+// newService 按当前配置创建邮件服务并设置死信存储和模板存储，调用方需持有锁
+func (m *EmailManager) newService() EmailService {
+	service := NewEmailService(m.config)
+	if svc, ok := service.(*emailService); ok {
+		svc.setDeadLetters(m.deadLetters, m.alerter)
+		svc.setTemplateStore(m.templates)
+	}
+	return service
+}
+
 // GetStats 获取邮件服务统计信息
 func (m *EmailManager) GetStats() (map[string]interface{}, error) {
 	m.mu.RLock()
@@ -146,6 +224,11 @@ func (m *EmailManager) GetStats() (map[string]interface{}, error) {
 		"healthy":     m.IsHealthy(),
 		"queue":       queueStatus,
 	}
+	if monitor, ok := m.service.(RouteMonitor); ok {
+		if routes := monitor.RouteStats(); routes != nil {
+			stats["routes"] = routes
+		}
+	}
 
 	return stats, nil
 }
@@ -195,6 +278,31 @@ func GetGlobalEmailService() EmailService {
 	return manager.GetService()
 }
 
+// SetGlobalDeadLetterStore 设置全局邮件服务的死信存储和告警器
+func SetGlobalDeadLetterStore(store DeadLetterStore, alerter *DeadLetterAlerter) {
+	GetGlobalEmailManager().SetDeadLetterStore(store, alerter)
+}
+
+// GetGlobalDeadLetterQueue 获取全局邮件服务的死信队列，服务未初始化时返回nil
+func GetGlobalDeadLetterQueue() DeadLetterQueue {
+	return GetGlobalEmailManager().GetDeadLetterQueue()
+}
+
+// GetGlobalRouteMonitor 获取全局邮件服务的发送线路监控接口，服务未初始化或没有配置发送线路时返回nil
+func GetGlobalRouteMonitor() RouteMonitor {
+	return GetGlobalEmailManager().GetRouteMonitor()
+}
+
+// SetGlobalTemplateStore 设置全局邮件服务的模板存储
+func SetGlobalTemplateStore(store TemplateStore) {
+	GetGlobalEmailManager().SetTemplateStore(store)
+}
+
+// GetGlobalTemplateManager 获取全局邮件服务的模板管理接口，服务未初始化时返回nil
+func GetGlobalTemplateManager() TemplateManager {
+	return GetGlobalEmailManager().GetTemplateManager()
+}
+
 // IsGlobalEmailServiceHealthy 检查全局邮件服务健康状态
 func IsGlobalEmailServiceHealthy() bool {
 	manager := GetGlobalEmailManager()
@@ -248,6 +356,14 @@ func QueueEmailGlobal(email *EmailQueue) error {
 	return service.QueueEmail(email)
 }
 
+// GlobalQueue 全局邮件队列，供只需要入队能力的业务服务注入
+type GlobalQueue struct{}
+
+// QueueEmail 将邮件加入全局队列
+func (GlobalQueue) QueueEmail(email *EmailQueue) error {
+	return QueueEmailGlobal(email)
+}
+
 // GetGlobalEmailStats 获取全局邮件服务统计信息
 func GetGlobalEmailStats() (map[string]interface{}, error) {
 	manager := GetGlobalEmailManager()
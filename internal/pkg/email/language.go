@@ -0,0 +1,66 @@
+package email
+
+import (
+	"context"
+	"sort"
+
+	"cloudpan/internal/pkg/i18n"
+)
+
+// languageContextKey 收件人语言在context中的键
+type languageContextKey struct{}
+
+// WithLanguage 返回携带收件人语言的context，SendTemplateEmail 及基于它的方法按该语言选择模板
+//
+// 使用示例：
+//
+//	ctx = email.WithLanguage(ctx, "en-US")
+//	emailService.SendVerificationCode(ctx, "user@example.com", "123456")
+func WithLanguage(ctx context.Context, language string) context.Context {
+	return context.WithValue(ctx, languageContextKey{}, i18n.Normalize(language))
+}
+
+// LanguageFromContext 返回context中的收件人语言，没有时返回空字符串
+func LanguageFromContext(ctx context.Context) string {
+	language, _ := ctx.Value(languageContextKey{}).(string)
+	return language
+}
+
+// resolveTemplate 按语言选择模板，返回模板和实际使用的语言
+//
+// 回退顺序：精确语言 → 基础语言相同的其他变体(如 en-GB 使用 en-US) → 配置的默认语言 → zh-CN
+func (s *emailService) resolveTemplate(name, language string) (*EmailTemplate, string, error) {
+	var candidates []string
+	if language = i18n.Normalize(language); language != "" {
+		candidates = append(candidates, language)
+		candidates = append(candidates, s.templateLanguages(name, i18n.Base(language))...)
+	}
+	candidates = append(candidates, s.config.DefaultLanguage, i18n.DefaultLanguage)
+
+	for _, candidate := range candidates {
+		if candidate == "" {
+			continue
+		}
+		if tmpl, err := s.GetTemplate(name, candidate); err == nil {
+			return tmpl, candidate, nil
+		}
+	}
+
+	_, err := s.GetTemplate(name, s.config.DefaultLanguage)
+	return nil, "", err
+}
+
+// templateLanguages 返回模板name已注册的、基础语言为base的语言，按字母顺序
+func (s *emailService) templateLanguages(name, base string) []string {
+	s.tmplMu.RLock()
+	defer s.tmplMu.RUnlock()
+
+	var languages []string
+	for _, tmpl := range s.templates {
+		if tmpl.Name == name && i18n.Base(tmpl.Language) == base {
+			languages = append(languages, tmpl.Language)
+		}
+	}
+	sort.Strings(languages)
+	return languages
+}
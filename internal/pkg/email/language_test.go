@@ -0,0 +1,65 @@
+package email
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithLanguage(t *testing.T) {
+	assert.Equal(t, "", LanguageFromContext(context.Background()))
+	assert.Equal(t, "en-US", LanguageFromContext(WithLanguage(context.Background(), "en")))
+}
+
+func TestEmailService_ResolveTemplate(t *testing.T) {
+	service := NewEmailService(nil).(*emailService)
+	require.NoError(t, service.LoadTemplates())
+
+	tests := []struct {
+		language string
+		expected string
+	}{
+		{"en-US", "en-US"},
+		{"en-GB", "en-US"}, // 基础语言相同
+		{"ja-JP", "zh-CN"}, // 没有日文模板，回退到默认语言
+		{"", "zh-CN"},
+	}
+	for _, tt := range tests {
+		tmpl, language, err := service.resolveTemplate(TemplateVerificationCode, tt.language)
+		require.NoError(t, err, tt.language)
+		assert.Equal(t, tt.expected, language, tt.language)
+		assert.Equal(t, tt.expected, tmpl.Language, tt.language)
+	}
+
+	_, _, err := service.resolveTemplate("nonexistent", "en-US")
+	assert.Error(t, err)
+}
+
+func TestEmailService_RenderLocalized(t *testing.T) {
+	service := NewEmailService(nil).(*emailService)
+	require.NoError(t, service.LoadTemplates())
+
+	tmpl, language, err := service.resolveTemplate(TemplateVerificationCode, "en-US")
+	require.NoError(t, err)
+
+	text, err := service.renderLocalized(tmpl.TextBody, language, map[string]interface{}{
+		"code": "123456", "expires_in": 1.0, "app_name": "CloudPan",
+	})
+	require.NoError(t, err)
+	assert.Contains(t, text, "This code expires in 1 minute\n")
+
+	text, err = service.renderLocalized(tmpl.TextBody, language, map[string]interface{}{
+		"code": "123456", "expires_in": 10.0, "app_name": "CloudPan",
+	})
+	require.NoError(t, err)
+	assert.Contains(t, text, "This code expires in 10 minutes")
+
+	for _, name := range []string{TemplatePasswordReset, TemplateWelcome, TemplateSecurityAlert, TemplateForcedPasswordReset} {
+		tmpl, err := service.GetTemplate(name, "en-US")
+		require.NoError(t, err, name)
+		_, err = service.renderLocalized(tmpl.HTMLBody, "en-US", map[string]interface{}{"expires_in": 2.0})
+		assert.NoError(t, err, name)
+	}
+}
@@ -0,0 +1,330 @@
+package email
+
+import (
+	"fmt"
+	"log"
+	"net/mail"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultRouteName 使用主SMTP配置的默认线路名称
+const DefaultRouteName = "default"
+
+// 线路降级的默认参数
+const (
+	defaultRouteFailureThreshold = 0.5
+	defaultRouteMinSamples       = 20
+	defaultRouteWindow           = 15 * time.Minute
+	defaultRouteDemoteDuration   = 10 * time.Minute
+)
+
+// RouteConfig 按收件人域名选择的发送线路，每条线路使用独立的SMTP服务器或IP池
+type RouteConfig struct {
+	Name     string     `mapstructure:"name" json:"name"`           // 线路名称，不能为default
+	SMTP     SMTPConfig `mapstructure:"smtp" json:"smtp"`           // 线路使用的SMTP服务器
+	From     string     `mapstructure:"from" json:"from"`           // 发件人邮箱，为空时使用默认发件人
+	FromName string     `mapstructure:"from_name" json:"from_name"` // 发件人名称，为空时使用默认发件人名称
+	// Domains 收件人域名，qq.com 匹配该域名及其子域名，以点开头的 .cn 按后缀匹配整个地区的域名
+	Domains []string `mapstructure:"domains" json:"domains"`
+}
+
+// RoutingOptions 线路投递统计和自动降级参数，零值使用默认值
+//
+// 统计窗口内发送次数达到 MinSamples 且失败率达到 FailureThreshold 时，线路降级 DemoteDuration，
+// 期间该线路的收件人改由下一条匹配的线路或默认线路发送；降级结束后线路重新统计
+type RoutingOptions struct {
+	FailureThreshold float64       `mapstructure:"failure_threshold" json:"failure_threshold"` // 降级的失败率，默认0.5
+	MinSamples       int           `mapstructure:"min_samples" json:"min_samples"`             // 统计窗口内判断降级的最少发送次数，默认20
+	Window           time.Duration `mapstructure:"window" json:"window"`                       // 统计窗口，默认15分钟
+	DemoteDuration   time.Duration `mapstructure:"demote_duration" json:"demote_duration"`     // 降级时长，默认10分钟
+}
+
+// validate 校验线路配置
+func (r *RouteConfig) validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("route name is required")
+	}
+	if r.Name == DefaultRouteName {
+		return fmt.Errorf("route name %q is reserved", DefaultRouteName)
+	}
+	if r.SMTP.Host == "" {
+		return fmt.Errorf("route %s: SMTP host is required", r.Name)
+	}
+	if r.SMTP.Port <= 0 || r.SMTP.Port > 65535 {
+		return fmt.Errorf("route %s: invalid SMTP port: %d", r.Name, r.SMTP.Port)
+	}
+	if r.SMTP.Username == "" || r.SMTP.Password == "" {
+		return fmt.Errorf("route %s: SMTP username and password are required", r.Name)
+	}
+	if len(r.Domains) == 0 {
+		return fmt.Errorf("route %s: at least one domain is required", r.Name)
+	}
+	return nil
+}
+
+// RouteStats 线路投递统计
+type RouteStats struct {
+	Name          string     `json:"name"`                      // 线路名称
+	Domains       []string   `json:"domains,omitempty"`         // 收件人域名，默认线路为空
+	Sent          int64      `json:"sent"`                      // 累计发送成功次数
+	Failed        int64      `json:"failed"`                    // 累计发送失败次数
+	WindowSent    int64      `json:"window_sent"`               // 当前统计窗口内发送成功次数
+	WindowFailed  int64      `json:"window_failed"`             // 当前统计窗口内发送失败次数
+	FailureRate   float64    `json:"failure_rate"`              // 当前统计窗口内的失败率
+	LastError     string     `json:"last_error,omitempty"`      // 最近一次失败原因
+	LastFailureAt *time.Time `json:"last_failure_at,omitempty"` // 最近一次失败时间
+	Demoted       bool       `json:"demoted"`                   // 是否处于降级中
+	DemotedUntil  *time.Time `json:"demoted_until,omitempty"`   // 降级结束时间
+	Demotions     int64      `json:"demotions"`                 // 累计降级次数
+	Rerouted      int64      `json:"rerouted"`                  // 降级期间改由其他线路发送的邮件数
+}
+
+// RouteMonitor 发送线路监控接口，由配置了线路的邮件服务实现
+type RouteMonitor interface {
+	// RouteStats 按匹配顺序返回各线路的投递统计，默认线路在最后
+	RouteStats() []*RouteStats
+}
+
+// emailRoute 发送线路及其投递统计
+type emailRoute struct {
+	name    string
+	domains []string
+	config  *EmailConfig // 线路的发送配置，SMTP和发件人替换为线路的值
+	pool    *smtpPool
+
+	stats       RouteStats
+	windowStart time.Time
+}
+
+// emailRouter 按收件人域名选择发送线路，记录投递结果并自动降级表现差的线路
+//
+// 默认线路使用主SMTP配置，是所有收件人的兜底线路，只统计不降级
+type emailRouter struct {
+	mu       sync.Mutex
+	routes   []*emailRoute // 按配置顺序匹配
+	fallback *emailRoute
+	options  RoutingOptions
+	now      func() time.Time
+}
+
+// newEmailRouter 按配置创建线路，没有配置线路时返回nil，此时始终使用默认SMTP配置发送
+func newEmailRouter(config *EmailConfig, pool *smtpPool) *emailRouter {
+	if len(config.Routes) == 0 {
+		return nil
+	}
+
+	options := config.Routing
+	if options.FailureThreshold <= 0 || options.FailureThreshold > 1 {
+		options.FailureThreshold = defaultRouteFailureThreshold
+	}
+	if options.MinSamples <= 0 {
+		options.MinSamples = defaultRouteMinSamples
+	}
+	if options.Window <= 0 {
+		options.Window = defaultRouteWindow
+	}
+	if options.DemoteDuration <= 0 {
+		options.DemoteDuration = defaultRouteDemoteDuration
+	}
+
+	router := &emailRouter{
+		fallback: &emailRoute{name: DefaultRouteName, config: config, pool: pool},
+		options:  options,
+		now:      time.Now,
+	}
+	for _, route := range config.Routes {
+		routeConfig := *config
+		routeConfig.SMTP = route.SMTP
+		routeConfig.Routes = nil
+		if route.From != "" {
+			routeConfig.From = route.From
+		}
+		if route.FromName != "" {
+			routeConfig.FromName = route.FromName
+		}
+		domains := make([]string, 0, len(route.Domains))
+		for _, domain := range route.Domains {
+			if domain = strings.ToLower(strings.TrimSpace(domain)); domain != "" {
+				domains = append(domains, domain)
+			}
+		}
+		router.routes = append(router.routes, &emailRoute{
+			name:    route.Name,
+			domains: domains,
+			config:  &routeConfig,
+			pool:    newSMTPPool(&routeConfig),
+		})
+	}
+	return router
+}
+
+// pick 按第一个收件人的域名选择线路，跳过降级中的线路；router为nil时返回nil
+//
+// 一封邮件只通过一条线路发送，多个收件人时按第一个收件人选择
+func (r *emailRouter) pick(to []string) *emailRoute {
+	if r == nil {
+		return nil
+	}
+	domain := ""
+	if len(to) > 0 {
+		domain = recipientDomain(to[0])
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	var demoted []*emailRoute
+	for _, route := range r.routes {
+		if !route.matches(domain) {
+			continue
+		}
+		if route.isDemoted(now) {
+			demoted = append(demoted, route)
+			continue
+		}
+		countRerouted(demoted)
+		return route
+	}
+	countRerouted(demoted)
+	return r.fallback
+}
+
+// record 记录线路的发送结果，失败率达到阈值时降级线路；route为nil时忽略
+func (r *emailRouter) record(route *emailRoute, err error) {
+	if r == nil || route == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	stats := &route.stats
+	if route.windowStart.IsZero() || now.Sub(route.windowStart) >= r.options.Window {
+		route.resetWindow(now)
+	}
+	if err == nil {
+		stats.Sent++
+		stats.WindowSent++
+		return
+	}
+
+	stats.Failed++
+	stats.WindowFailed++
+	stats.LastError = err.Error()
+	stats.LastFailureAt = &now
+
+	if route == r.fallback || route.isDemoted(now) {
+		return
+	}
+	total := stats.WindowSent + stats.WindowFailed
+	if total < int64(r.options.MinSamples) {
+		return
+	}
+	rate := float64(stats.WindowFailed) / float64(total)
+	if rate < r.options.FailureThreshold {
+		return
+	}
+
+	until := now.Add(r.options.DemoteDuration)
+	stats.DemotedUntil = &until
+	stats.Demotions++
+	log.Printf("Email route %s demoted until %s: failure rate %.2f over %d sends, last error: %v",
+		route.name, until.Format(time.RFC3339), rate, total, err)
+	// 降级结束后重新统计，避免降级前的失败再次触发降级
+	route.resetWindow(until)
+}
+
+// stats 返回各线路统计的副本，默认线路在最后
+func (r *emailRouter) stats() []*RouteStats {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	routes := append(append([]*emailRoute{}, r.routes...), r.fallback)
+	result := make([]*RouteStats, 0, len(routes))
+	for _, route := range routes {
+		stats := route.stats
+		stats.Name = route.name
+		stats.Domains = append([]string(nil), route.domains...)
+		if route.windowStart.IsZero() || now.Sub(route.windowStart) >= r.options.Window {
+			stats.WindowSent, stats.WindowFailed = 0, 0
+		}
+		if total := stats.WindowSent + stats.WindowFailed; total > 0 {
+			stats.FailureRate = float64(stats.WindowFailed) / float64(total)
+		}
+		stats.Demoted = route.isDemoted(now)
+		if !stats.Demoted {
+			stats.DemotedUntil = nil
+		}
+		result = append(result, &stats)
+	}
+	return result
+}
+
+// close 关闭各线路的连接池，默认线路的连接池由邮件服务关闭
+func (r *emailRouter) close() {
+	if r == nil {
+		return
+	}
+	for _, route := range r.routes {
+		route.pool.Close()
+	}
+}
+
+// matches 检查收件人域名是否属于该线路
+func (route *emailRoute) matches(domain string) bool {
+	if domain == "" {
+		return false
+	}
+	for _, rule := range route.domains {
+		if strings.HasPrefix(rule, ".") {
+			if strings.HasSuffix(domain, rule) {
+				return true
+			}
+			continue
+		}
+		if domain == rule || strings.HasSuffix(domain, "."+rule) {
+			return true
+		}
+	}
+	return false
+}
+
+// isDemoted 检查线路在指定时间是否处于降级中
+func (route *emailRoute) isDemoted(now time.Time) bool {
+	return route.stats.DemotedUntil != nil && now.Before(*route.stats.DemotedUntil)
+}
+
+// countRerouted 记录因降级被跳过的线路
+func countRerouted(skipped []*emailRoute) {
+	for _, demoted := range skipped {
+		demoted.stats.Rerouted++
+	}
+}
+
+// resetWindow 从指定时间开始新的统计窗口
+func (route *emailRoute) resetWindow(start time.Time) {
+	route.windowStart = start
+	route.stats.WindowSent = 0
+	route.stats.WindowFailed = 0
+}
+
+// recipientDomain 返回收件人地址的小写域名，支持 "名称 <地址>" 格式
+func recipientDomain(address string) string {
+	if parsed, err := mail.ParseAddress(address); err == nil {
+		address = parsed.Address
+	}
+	at := strings.LastIndex(address, "@")
+	if at < 0 {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(strings.TrimSuffix(address[at+1:], ">")))
+}
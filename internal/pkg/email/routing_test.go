@@ -0,0 +1,140 @@
+package email
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRouter(t *testing.T, options RoutingOptions) (*emailRouter, *time.Time) {
+	config := DefaultEmailConfig()
+	config.From = "noreply@example.com"
+	config.Routes = []RouteConfig{
+		{Name: "cn", From: "noreply@mail.example.cn", Domains: []string{"QQ.com", "163.com", ".cn"},
+			SMTP: SMTPConfig{Host: "smtp-cn.example.com", Port: 587, Username: "u", Password: "p"}},
+		{Name: "cn-backup", Domains: []string{"qq.com"},
+			SMTP: SMTPConfig{Host: "smtp-cn2.example.com", Port: 587, Username: "u", Password: "p"}},
+	}
+	config.Routing = options
+	pool := newSMTPPool(config)
+	router := newEmailRouter(config, pool)
+	t.Cleanup(func() {
+		router.close()
+		pool.Close()
+	})
+
+	now := time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC)
+	router.now = func() time.Time { return now }
+	return router, &now
+}
+
+func TestEmailRouter_Pick(t *testing.T) {
+	router, _ := newTestRouter(t, RoutingOptions{})
+
+	assert.Nil(t, newEmailRouter(DefaultEmailConfig(), nil), "没有配置线路时不创建路由")
+	assert.Nil(t, (*emailRouter)(nil).pick([]string{"a@qq.com"}))
+
+	cases := map[string]string{
+		"alice@qq.com":             "cn",
+		"Alice <Alice@VIP.QQ.com>": "cn",
+		"bob@mail.tsinghua.edu.cn": "cn",
+		"carol@gmail.com":          DefaultRouteName,
+		"dave@notqq.com":           DefaultRouteName,
+		"eve@cn.example.com":       DefaultRouteName,
+		"invalid-address":          DefaultRouteName,
+	}
+	for to, expected := range cases {
+		assert.Equal(t, expected, router.pick([]string{to}).name, to)
+	}
+	assert.Equal(t, DefaultRouteName, router.pick(nil).name)
+
+	route := router.pick([]string{"alice@qq.com"})
+	assert.Equal(t, "noreply@mail.example.cn", route.config.From, "线路使用自己的发件人")
+	assert.Equal(t, "smtp-cn.example.com:587", route.config.GetSMTPAddress())
+	assert.Equal(t, "HXLOS Cloud", route.config.FromName, "未配置时使用默认发件人名称")
+	assert.Equal(t, "noreply@example.com", router.fallback.config.From)
+}
+
+func TestEmailRouter_Demotion(t *testing.T) {
+	router, now := newTestRouter(t, RoutingOptions{MinSamples: 4, FailureThreshold: 0.5, Window: time.Minute, DemoteDuration: 10 * time.Minute})
+	cn := router.routes[0]
+	sendErr := errors.New("421 connection rate limited")
+
+	router.record(cn, nil)
+	router.record(cn, sendErr)
+	router.record(cn, sendErr)
+	assert.False(t, cn.isDemoted(*now), "发送次数不足时不降级")
+
+	router.record(cn, nil)
+	assert.False(t, cn.isDemoted(*now))
+	router.record(cn, sendErr)
+	require.True(t, cn.isDemoted(*now), "失败率达到阈值时降级")
+
+	// 降级期间QQ邮箱改用下一条匹配的线路，其他国内域名改用默认线路
+	assert.Equal(t, "cn-backup", router.pick([]string{"alice@qq.com"}).name)
+	assert.Equal(t, DefaultRouteName, router.pick([]string{"bob@163.com"}).name)
+
+	stats := router.stats()
+	require.Len(t, stats, 3)
+	assert.Equal(t, "cn", stats[0].Name)
+	assert.True(t, stats[0].Demoted)
+	assert.Equal(t, int64(1), stats[0].Demotions)
+	assert.Equal(t, int64(2), stats[0].Rerouted)
+	assert.Equal(t, int64(2), stats[0].Sent)
+	assert.Equal(t, int64(3), stats[0].Failed)
+	assert.Equal(t, "421 connection rate limited", stats[0].LastError)
+	assert.Equal(t, DefaultRouteName, stats[2].Name)
+
+	// 降级结束后恢复并重新统计
+	*now = now.Add(10 * time.Minute)
+	assert.Equal(t, "cn", router.pick([]string{"alice@qq.com"}).name)
+	router.record(cn, sendErr)
+	assert.False(t, cn.isDemoted(*now), "降级前的失败不再计入")
+	stats = router.stats()
+	assert.False(t, stats[0].Demoted)
+	assert.Nil(t, stats[0].DemotedUntil)
+	assert.Equal(t, int64(1), stats[0].WindowFailed)
+	assert.InDelta(t, 1.0, stats[0].FailureRate, 0.001)
+
+	// 统计窗口过期后窗口内的计数清零
+	*now = now.Add(2 * time.Minute)
+	stats = router.stats()
+	assert.Zero(t, stats[0].WindowFailed)
+	assert.Zero(t, stats[0].FailureRate)
+
+	// 默认线路只统计不降级
+	for i := 0; i < 10; i++ {
+		router.record(router.fallback, sendErr)
+	}
+	assert.False(t, router.fallback.isDemoted(*now))
+	assert.Equal(t, DefaultRouteName, router.pick([]string{"carol@gmail.com"}).name)
+}
+
+func TestEmailConfig_ValidateRoutes(t *testing.T) {
+	newConfig := func(routes ...RouteConfig) *EmailConfig {
+		config := DefaultEmailConfig()
+		config.SMTP.Username, config.SMTP.Password, config.From = "u", "p", "noreply@example.com"
+		config.Routes = routes
+		return config
+	}
+	valid := RouteConfig{Name: "cn", Domains: []string{"qq.com"},
+		SMTP: SMTPConfig{Host: "smtp-cn.example.com", Port: 587, Username: "u", Password: "p"}}
+
+	assert.NoError(t, newConfig(valid).Validate())
+	assert.Error(t, newConfig(valid, valid).Validate(), "线路名称重复")
+
+	reserved := valid
+	reserved.Name = DefaultRouteName
+	assert.Error(t, newConfig(reserved).Validate())
+
+	noDomains := valid
+	noDomains.Domains = nil
+	assert.Error(t, newConfig(noDomains).Validate())
+
+	noHost := valid
+	noHost.SMTP.Host = ""
+	assert.Error(t, newConfig(noHost).Validate())
+}
@@ -3,6 +3,7 @@ package email
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"html/template"
 	"log"
@@ -11,8 +12,19 @@ import (
 	"time"
 
 	"github.com/jordan-wright/email"
+
+	"cloudpan/internal/pkg/i18n"
+)
+
+// 队列操作错误
+var (
+	ErrQueueFull         = errors.New("email queue is full")          // 队列已满
+	ErrServiceNotRunning = errors.New("email service is not running") // 服务未启动或已停止
 )
 
+// deadLetterTimeout 写入死信存储和检查告警的超时时间，服务停止时仍能保存最后失败的邮件
+const deadLetterTimeout = 5 * time.Second
+
 // EmailService 邮件服务接口
 //
 // 提供完整的邮件发送和管理功能，包括：
@@ -59,13 +71,22 @@ type EmailService interface {
 type emailService struct {
 	config    *EmailConfig
 	pool      *smtpPool
-	templates map[string]*EmailTemplate
+	router    *emailRouter              // 按收件人域名选择发送线路，没有配置线路时为nil
+	templates map[string]*EmailTemplate // 生效的模板，键为 名称_语言
 	queue     chan *EmailQueue
 	wg        sync.WaitGroup
 	ctx       context.Context
 	cancel    context.CancelFunc
 	mu        sync.RWMutex
 	isRunning bool
+
+	deadLetters DeadLetterStore    // 达到最大重试次数仍失败的邮件
+	alerter     *DeadLetterAlerter // 死信数量告警，为nil时不告警
+
+	tmplMu             sync.RWMutex              // 保护 templates、base、templateStore 和 overridesCheckedAt
+	base               map[string]*EmailTemplate // 内置、模板目录和 RegisterTemplate 注册的模板，不含管理员修改
+	templateStore      TemplateStore             // 管理员修改的模板，为nil时不能在线修改模板
+	overridesCheckedAt time.Time                 // 上次加载模板存储的时间
 }
 
 // NewEmailService 创建邮件服务实例
@@ -75,14 +96,19 @@ func NewEmailService(config *EmailConfig) EmailService {
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
+	pool := newSMTPPool(config)
 
 	service := &emailService{
 		config:    config,
-		pool:      newSMTPPool(config),
+		pool:      pool,
+		router:    newEmailRouter(config, pool),
 		templates: make(map[string]*EmailTemplate),
+		base:      make(map[string]*EmailTemplate),
 		queue:     make(chan *EmailQueue, 1000), // 队列容量1000
 		ctx:       ctx,
 		cancel:    cancel,
+
+		deadLetters: NewMemoryDeadLetterStore(),
 	}
 
 	return service
@@ -130,6 +156,7 @@ func (s *emailService) Stop() error {
 	s.wg.Wait()
 
 	s.pool.Close()
+	s.router.close()
 	s.isRunning = false
 	log.Println("Email service stopped")
 	return nil
@@ -169,26 +196,33 @@ func (s *emailService) SendHTMLEmail(ctx context.Context, to []string, subject,
 }
 
 // SendTemplateEmail 发送模板邮件
+//
+// 按 WithLanguage 设置的收件人语言选择模板变体，没有该语言的模板时回退，见 resolveTemplate；
+// variables 缺少模板引用的变量时不发送，返回 ErrMissingVariables
 func (s *emailService) SendTemplateEmail(ctx context.Context, templateName string, to []string, variables map[string]interface{}) error {
-	tmpl, err := s.GetTemplate(templateName, s.config.DefaultLanguage)
+	s.refreshOverrides(ctx)
+	tmpl, language, err := s.resolveTemplate(templateName, LanguageFromContext(ctx))
 	if err != nil {
 		return fmt.Errorf("failed to get template: %w", err)
 	}
+	if err := checkVariables(tmpl, variables); err != nil {
+		return fmt.Errorf("failed to render template %s: %w", templateName, err)
+	}
 
 	// 渲染主题
-	subject, err := s.renderTemplate(tmpl.Subject, variables)
+	subject, err := s.renderLocalized(tmpl.Subject, language, variables)
 	if err != nil {
 		return fmt.Errorf("failed to render subject: %w", err)
 	}
 
 	// 渲染HTML内容
-	htmlBody, err := s.renderTemplate(tmpl.HTMLBody, variables)
+	htmlBody, err := s.renderLocalized(tmpl.HTMLBody, language, variables)
 	if err != nil {
 		return fmt.Errorf("failed to render HTML body: %w", err)
 	}
 
 	// 渲染文本内容
-	textBody, err := s.renderTemplate(tmpl.TextBody, variables)
+	textBody, err := s.renderLocalized(tmpl.TextBody, language, variables)
 	if err != nil {
 		return fmt.Errorf("failed to render text body: %w", err)
 	}
@@ -262,7 +296,7 @@ func (s *emailService) QueueEmail(emailItem *EmailQueue) error {
 	case s.queue <- emailItem:
 		return nil
 	default:
-		return fmt.Errorf("email queue is full")
+		return ErrQueueFull
 	}
 }
 
@@ -273,28 +307,49 @@ func (s *emailService) ProcessQueue(ctx context.Context) error {
 	return nil
 }
 
-// GetQueueStatus 获取队列状态
+// GetQueueStatus 获取队列状态，dead_letter为死信数量
 func (s *emailService) GetQueueStatus() (map[string]int, error) {
+	deadLetters, err := s.deadLetterStore().Count(context.Background())
+	if err != nil {
+		return nil, err
+	}
 	status := map[string]int{
-		"pending": len(s.queue),
-		"total":   cap(s.queue),
+		"pending":     len(s.queue),
+		"total":       cap(s.queue),
+		"dead_letter": int(deadLetters),
 	}
 	return status, nil
 }
 
 // LoadTemplates 加载邮件模板
+//
+// 依次加载内置模板、模板目录中的文件和模板存储中管理员修改的模板，后者覆盖前者；
+// 模板存储加载失败时只记录日志，使用内置和模板目录中的模板
 func (s *emailService) LoadTemplates() error {
 	// 注册默认模板
-	defaultTemplates := s.getDefaultTemplates()
-	for _, tmpl := range defaultTemplates {
-		s.templates[tmpl.Name+"_"+tmpl.Language] = tmpl
+	s.tmplMu.Lock()
+	for _, tmpl := range s.getDefaultTemplates() {
+		s.base[templateKey(tmpl.Name, tmpl.Language)] = tmpl
 	}
+	s.tmplMu.Unlock()
 
 	// 如果配置了模板目录，从文件系统加载模板
 	if s.config.TemplateDir != "" {
-		return s.loadTemplatesFromDir(s.config.TemplateDir)
+		if err := s.loadTemplatesFromDir(s.config.TemplateDir); err != nil {
+			return err
+		}
+	}
+
+	s.tmplMu.Lock()
+	s.templates = make(map[string]*EmailTemplate, len(s.base))
+	for key, tmpl := range s.base {
+		s.templates[key] = tmpl
 	}
+	s.tmplMu.Unlock()
 
+	if err := s.loadOverrides(context.Background()); err != nil {
+		log.Printf("Failed to load customized email templates: %v", err)
+	}
 	return nil
 }
 
@@ -307,7 +362,10 @@ func (s *emailService) RegisterTemplate(template *EmailTemplate) error {
 		template.Language = s.config.DefaultLanguage
 	}
 
-	key := template.Name + "_" + template.Language
+	key := templateKey(template.Name, template.Language)
+	s.tmplMu.Lock()
+	defer s.tmplMu.Unlock()
+	s.base[key] = template
 	s.templates[key] = template
 	return nil
 }
@@ -318,8 +376,10 @@ func (s *emailService) GetTemplate(name, language string) (*EmailTemplate, error
 		language = s.config.DefaultLanguage
 	}
 
-	key := name + "_" + language
+	key := templateKey(name, language)
+	s.tmplMu.RLock()
 	template, exists := s.templates[key]
+	s.tmplMu.RUnlock()
 	if !exists {
 		return nil, fmt.Errorf("template not found: %s", key)
 	}
@@ -332,12 +392,22 @@ func (s *emailService) GetTemplate(name, language string) (*EmailTemplate, error
 }
 
 // sendEmail 发送邮件的内部方法
+//
+// 配置了发送线路时按收件人域名选择线路，使用线路的SMTP服务器和发件人发送并记录投递结果
 func (s *emailService) sendEmail(ctx context.Context, e *email.Email) error {
-	conn, err := s.pool.Get()
+	config, pool := s.config, s.pool
+	route := s.router.pick(e.To)
+	if route != nil {
+		config, pool = route.config, route.pool
+		e.From = config.GetFromAddress()
+	}
+
+	conn, err := pool.Get()
 	if err != nil {
+		s.router.record(route, err)
 		return fmt.Errorf("failed to get SMTP connection: %w", err)
 	}
-	defer s.pool.Put(conn)
+	defer pool.Put(conn)
 
 	// 设置超时
 	timeoutCtx, cancel := context.WithTimeout(ctx, s.config.GetTimeout())
@@ -351,17 +421,29 @@ func (s *emailService) sendEmail(ctx context.Context, e *email.Email) error {
 	}
 
 	// 发送邮件
-	return e.Send(s.config.GetSMTPAddress(), s.getSMTPAuth())
+	err = e.Send(config.GetSMTPAddress(), getSMTPAuth(config))
+	s.router.record(route, err)
+	return err
+}
+
+// RouteStats 返回各发送线路的投递统计，没有配置线路时返回nil
+func (s *emailService) RouteStats() []*RouteStats {
+	return s.router.stats()
 }
 
 // getSMTPAuth 获取SMTP认证
-func (s *emailService) getSMTPAuth() smtp.Auth {
-	return smtp.PlainAuth("", s.config.SMTP.Username, s.config.SMTP.Password, s.config.SMTP.Host)
+func getSMTPAuth(config *EmailConfig) smtp.Auth {
+	return smtp.PlainAuth("", config.SMTP.Username, config.SMTP.Password, config.SMTP.Host)
 }
 
-// renderTemplate 渲染模板
+// renderTemplate 按默认语言渲染模板
 func (s *emailService) renderTemplate(tmplStr string, variables map[string]interface{}) (string, error) {
-	tmpl, err := template.New("email").Parse(tmplStr)
+	return s.renderLocalized(tmplStr, s.config.DefaultLanguage, variables)
+}
+
+// renderLocalized 按语言渲染模板，模板中可使用 i18n.FuncMap 提供的 plural 和 count 函数
+func (s *emailService) renderLocalized(tmplStr, language string, variables map[string]interface{}) (string, error) {
+	tmpl, err := template.New("email").Funcs(i18n.FuncMap(language)).Parse(tmplStr)
 	if err != nil {
 		return "", err
 	}
@@ -396,18 +478,24 @@ func (s *emailService) processEmailItem(emailItem *EmailQueue) {
 	emailItem.Status = EmailStatusSending
 	emailItem.UpdatedAt = time.Now()
 
+	ctx := s.ctx
+	if emailItem.Language != "" {
+		ctx = WithLanguage(ctx, emailItem.Language)
+	}
+
 	var err error
 	if emailItem.Template != "" {
 		// 使用模板发送
-		err = s.SendTemplateEmail(s.ctx, emailItem.Template, emailItem.To, emailItem.Variables)
+		err = s.SendTemplateEmail(ctx, emailItem.Template, emailItem.To, emailItem.Variables)
 	} else {
 		// 直接发送
-		err = s.SendHTMLEmail(s.ctx, emailItem.To, emailItem.Subject, emailItem.HTMLBody, emailItem.TextBody)
+		err = s.SendHTMLEmail(ctx, emailItem.To, emailItem.Subject, emailItem.HTMLBody, emailItem.TextBody)
 	}
 
 	if err != nil {
 		emailItem.Attempts++
 		emailItem.ErrorMsg = err.Error()
+		emailItem.Errors = append(emailItem.Errors, err.Error())
 		emailItem.UpdatedAt = time.Now()
 
 		if emailItem.Attempts < emailItem.MaxAttempts {
@@ -417,8 +505,9 @@ func (s *emailService) processEmailItem(emailItem *EmailQueue) {
 				s.queue <- emailItem
 			})
 		} else {
-			// 达到最大重试次数，标记为失败
+			// 达到最大重试次数，标记为失败并转入死信队列
 			emailItem.Status = EmailStatusFailed
+			s.moveToDeadLetter(emailItem)
 		}
 	} else {
 		emailItem.Status = EmailStatusSent
@@ -426,13 +515,6 @@ func (s *emailService) processEmailItem(emailItem *EmailQueue) {
 	}
 }
 
-// loadTemplatesFromDir 从目录加载模板
-func (s *emailService) loadTemplatesFromDir(_ string) error {
-	// 这里可以实现从文件系统加载模板的逻辑
-	// 暂时使用默认模板
-	return nil
-}
-
 // generateEmailID 生成邮件ID
 func generateEmailID() string {
 	return fmt.Sprintf("email_%d", time.Now().UnixNano())
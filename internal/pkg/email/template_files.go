@@ -0,0 +1,146 @@
+package email
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"cloudpan/internal/pkg/i18n"
+)
+
+// 模板目录中的文件扩展名
+const (
+	templateFileSubject = ".subject" // 邮件主题
+	templateFileHTML    = ".html"    // HTML内容
+	templateFileText    = ".txt"     // 纯文本内容
+)
+
+// loadTemplatesFromDir 从目录加载模板，加载到 base 中覆盖内置模板
+//
+// 目录结构为 <dir>/<语言>/<模板名称>.subject|.html|.txt，语言需在 i18n.SupportedLanguages 中。
+// 缺少的部分沿用同名同语言的内置模板；新名称的模板需要主题和至少一种正文，声明的变量为模板引用的变量。
+// 目录不存在时忽略，无法解析的模板记录日志后跳过
+func (s *emailService) loadTemplatesFromDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			log.Printf("Email template directory %s does not exist, using built-in templates", dir)
+			return nil
+		}
+		return fmt.Errorf("failed to read template directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		language := i18n.Normalize(entry.Name())
+		if !isSupportedTemplateLanguage(language) {
+			log.Printf("Skipping email templates in %s: unsupported language", entry.Name())
+			continue
+		}
+		if err := s.loadLanguageTemplates(filepath.Join(dir, entry.Name()), language); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadLanguageTemplates 加载一种语言的模板文件
+func (s *emailService) loadLanguageTemplates(dir, language string) error {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read template directory: %w", err)
+	}
+
+	parts := make(map[string]map[string]string)
+	for _, file := range files {
+		ext := filepath.Ext(file.Name())
+		if file.IsDir() || ext != templateFileSubject && ext != templateFileHTML && ext != templateFileText {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return fmt.Errorf("failed to read template file: %w", err)
+		}
+		name := strings.TrimSuffix(file.Name(), ext)
+		if parts[name] == nil {
+			parts[name] = make(map[string]string)
+		}
+		parts[name][ext] = string(content)
+	}
+
+	names := make([]string, 0, len(parts))
+	for name := range parts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	s.tmplMu.Lock()
+	defer s.tmplMu.Unlock()
+	for _, name := range names {
+		tmpl := s.fileTemplate(name, language, parts[name])
+		if tmpl == nil {
+			log.Printf("Skipping email template %s: subject and at least one body are required", templateKey(name, language))
+			continue
+		}
+		if err := validateTemplate(tmpl); err != nil {
+			log.Printf("Skipping email template %s: %v", templateKey(name, language), err)
+			continue
+		}
+		s.base[templateKey(name, language)] = tmpl
+	}
+	return nil
+}
+
+// fileTemplate 合并模板文件和同名同语言的已有模板，调用方需持有写锁；内容不完整时返回nil
+func (s *emailService) fileTemplate(name, language string, parts map[string]string) *EmailTemplate {
+	tmpl := &EmailTemplate{Name: name, Language: language, IsActive: true}
+	if existing, ok := s.base[templateKey(name, language)]; ok {
+		copied := *existing
+		tmpl = &copied
+	} else if variables, description, known := s.declaredVariables(name, language); known {
+		tmpl.Variables, tmpl.Description = variables, description
+	}
+	tmpl.Source = TemplateSourceFile
+
+	if subject, ok := parts[templateFileSubject]; ok {
+		tmpl.Subject = strings.TrimSpace(subject)
+	}
+	if html, ok := parts[templateFileHTML]; ok {
+		tmpl.HTMLBody = html
+	}
+	if text, ok := parts[templateFileText]; ok {
+		tmpl.TextBody = text
+	}
+	if tmpl.Subject == "" || tmpl.HTMLBody == "" && tmpl.TextBody == "" {
+		return nil
+	}
+
+	if tmpl.Variables == nil {
+		references, err := templateReferences(tmpl)
+		if err != nil {
+			// 语法错误由 validateTemplate 报告
+			return tmpl
+		}
+		tmpl.Variables = make(map[string]string, len(references))
+		for _, reference := range references {
+			tmpl.Variables[reference] = ""
+		}
+	}
+	return tmpl
+}
+
+// isSupportedTemplateLanguage 语言是否在 i18n.SupportedLanguages 中
+func isSupportedTemplateLanguage(language string) bool {
+	for _, supported := range i18n.SupportedLanguages {
+		if language == supported {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,489 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"sort"
+	"strings"
+	"text/template/parse"
+	"time"
+
+	"cloudpan/internal/pkg/i18n"
+)
+
+// 模板来源
+const (
+	TemplateSourceDefault  = "default"  // 代码内置
+	TemplateSourceFile     = "file"     // 模板目录中的文件
+	TemplateSourceDatabase = "database" // 管理员通过接口修改，保存在模板存储中
+)
+
+const (
+	// templateRefreshInterval 发送模板邮件时重新加载模板存储的最短间隔，多实例部署时其他实例的修改在该间隔内生效
+	templateRefreshInterval = time.Minute
+	// templateStoreTimeout 加载模板存储的超时时间
+	templateStoreTimeout = 5 * time.Second
+)
+
+// 模板管理错误
+var (
+	ErrTemplateNotFound         = errors.New("email template not found")               // 模板或语言版本不存在
+	ErrInvalidTemplate          = errors.New("invalid email template")                 // 模板语法错误或使用了未声明的变量
+	ErrMissingVariables         = errors.New("missing email template variables")       // 渲染时缺少模板引用的变量
+	ErrTemplateStoreUnavailable = errors.New("email template store is not configured") // 未设置模板存储，不能在线修改模板
+	ErrUnsupportedLanguage      = errors.New("unsupported email template language")    // 语言不在 i18n.SupportedLanguages 中
+)
+
+// TemplateStore 管理员修改的模板存储，保存的模板覆盖内置和模板目录中同名同语言的模板
+//
+// 使用示例：
+//
+//	email.SetGlobalTemplateStore(systemrepo.NewEmailTemplateRepository(db))
+type TemplateStore interface {
+	// ListTemplates 返回全部修改过的模板
+	ListTemplates(ctx context.Context) ([]*EmailTemplate, error)
+	// SaveTemplate 保存模板，名称和语言相同时覆盖
+	SaveTemplate(ctx context.Context, tmpl *EmailTemplate) error
+	// DeleteTemplate 删除修改过的模板，不存在时返回false
+	DeleteTemplate(ctx context.Context, name, language string) (bool, error)
+}
+
+// TemplateDraft 修改或预览的模板内容
+type TemplateDraft struct {
+	Subject  string `json:"subject"`             // 邮件主题
+	HTMLBody string `json:"html_body"`           // HTML内容
+	TextBody string `json:"text_body"`           // 纯文本内容
+	IsActive *bool  `json:"is_active,omitempty"` // 是否启用，为空时启用
+}
+
+// RenderedEmail 模板渲染结果
+type RenderedEmail struct {
+	Name      string                 `json:"name"`      // 模板名称
+	Language  string                 `json:"language"`  // 语言
+	Subject   string                 `json:"subject"`   // 渲染后的主题
+	HTMLBody  string                 `json:"html_body"` // 渲染后的HTML内容
+	TextBody  string                 `json:"text_body"` // 渲染后的纯文本内容
+	Variables map[string]interface{} `json:"variables"` // 渲染使用的变量
+}
+
+// TemplateManager 邮件模板管理接口，由 NewEmailService 创建的服务实现
+//
+// 模板按 名称+语言 区分版本，生效的模板依次为：内置模板 → 模板目录中的文件 → 管理员修改(保存在模板存储中)，
+// 后者覆盖前者。只能修改已有名称的模板，可以为其新增 i18n.SupportedLanguages 中的语言版本；
+// 模板只能引用该名称声明的变量，发送时缺少引用的变量返回 ErrMissingVariables
+//
+// 使用示例：
+//
+//	manager := email.GetGlobalTemplateManager()
+//	preview, err := manager.PreviewTemplate(email.TemplateWelcome, "en-US", &email.TemplateDraft{Subject: "Hi {{.username}}", TextBody: "..."}, nil)
+//	tmpl, err := manager.UpdateTemplate(ctx, email.TemplateWelcome, "en-US", draft, adminID)
+//	tmpl, err = manager.ResetTemplate(ctx, email.TemplateWelcome, "en-US")
+type TemplateManager interface {
+	// ListTemplates 返回全部生效的模板，按名称和语言排序，包括未启用的模板
+	ListTemplates() []*EmailTemplate
+	// GetTemplateVariant 返回指定名称和语言的生效模板，包括未启用的模板
+	GetTemplateVariant(name, language string) (*EmailTemplate, error)
+	// PreviewTemplate 渲染模板，draft为nil时渲染生效的模板；variables为nil时使用变量名作为示例值
+	PreviewTemplate(name, language string, draft *TemplateDraft, variables map[string]interface{}) (*RenderedEmail, error)
+	// UpdateTemplate 校验并保存修改后的模板，立即生效
+	UpdateTemplate(ctx context.Context, name, language string, draft *TemplateDraft, updatedBy uint) (*EmailTemplate, error)
+	// ResetTemplate 删除管理员的修改，恢复为模板目录或内置的模板；该语言版本只存在于修改中时删除该版本并返回nil
+	ResetTemplate(ctx context.Context, name, language string) (*EmailTemplate, error)
+}
+
+// ListTemplates 返回全部生效的模板
+func (s *emailService) ListTemplates() []*EmailTemplate {
+	s.tmplMu.RLock()
+	templates := make([]*EmailTemplate, 0, len(s.templates))
+	for _, tmpl := range s.templates {
+		copied := *tmpl
+		templates = append(templates, &copied)
+	}
+	s.tmplMu.RUnlock()
+
+	sort.Slice(templates, func(i, j int) bool {
+		if templates[i].Name != templates[j].Name {
+			return templates[i].Name < templates[j].Name
+		}
+		return templates[i].Language < templates[j].Language
+	})
+	return templates
+}
+
+// GetTemplateVariant 返回指定名称和语言的生效模板
+func (s *emailService) GetTemplateVariant(name, language string) (*EmailTemplate, error) {
+	language, err := s.templateLanguage(language)
+	if err != nil {
+		return nil, err
+	}
+	s.tmplMu.RLock()
+	defer s.tmplMu.RUnlock()
+
+	tmpl, ok := s.templates[templateKey(name, language)]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, templateKey(name, language))
+	}
+	copied := *tmpl
+	return &copied, nil
+}
+
+// PreviewTemplate 渲染模板但不发送
+func (s *emailService) PreviewTemplate(name, language string, draft *TemplateDraft, variables map[string]interface{}) (*RenderedEmail, error) {
+	language, err := s.templateLanguage(language)
+	if err != nil {
+		return nil, err
+	}
+
+	var tmpl *EmailTemplate
+	if draft == nil {
+		if tmpl, err = s.GetTemplateVariant(name, language); err != nil {
+			return nil, err
+		}
+	} else if tmpl, err = s.draftTemplate(name, language, draft); err != nil {
+		return nil, err
+	}
+
+	if variables == nil {
+		references, err := templateReferences(tmpl)
+		if err != nil {
+			return nil, err
+		}
+		variables = make(map[string]interface{}, len(references))
+		for _, name := range references {
+			variables[name] = "[" + name + "]"
+		}
+	} else if err := checkVariables(tmpl, variables); err != nil {
+		return nil, err
+	}
+
+	rendered := &RenderedEmail{Name: name, Language: language, Variables: variables}
+	if rendered.Subject, err = s.renderLocalized(tmpl.Subject, language, variables); err != nil {
+		return nil, fmt.Errorf("%w: subject: %v", ErrInvalidTemplate, err)
+	}
+	if rendered.HTMLBody, err = s.renderLocalized(tmpl.HTMLBody, language, variables); err != nil {
+		return nil, fmt.Errorf("%w: html body: %v", ErrInvalidTemplate, err)
+	}
+	if rendered.TextBody, err = s.renderLocalized(tmpl.TextBody, language, variables); err != nil {
+		return nil, fmt.Errorf("%w: text body: %v", ErrInvalidTemplate, err)
+	}
+	return rendered, nil
+}
+
+// UpdateTemplate 校验并保存修改后的模板
+func (s *emailService) UpdateTemplate(ctx context.Context, name, language string, draft *TemplateDraft, updatedBy uint) (*EmailTemplate, error) {
+	store := s.getTemplateStore()
+	if store == nil {
+		return nil, ErrTemplateStoreUnavailable
+	}
+	language, err := s.templateLanguage(language)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := s.draftTemplate(name, language, draft)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	tmpl.Source, tmpl.UpdatedBy, tmpl.UpdatedAt = TemplateSourceDatabase, updatedBy, &now
+	if err := store.SaveTemplate(ctx, tmpl); err != nil {
+		return nil, fmt.Errorf("failed to save template: %w", err)
+	}
+
+	s.tmplMu.Lock()
+	s.templates[templateKey(name, language)] = tmpl
+	s.tmplMu.Unlock()
+
+	copied := *tmpl
+	return &copied, nil
+}
+
+// ResetTemplate 删除管理员的修改
+func (s *emailService) ResetTemplate(ctx context.Context, name, language string) (*EmailTemplate, error) {
+	store := s.getTemplateStore()
+	if store == nil {
+		return nil, ErrTemplateStoreUnavailable
+	}
+	language, err := s.templateLanguage(language)
+	if err != nil {
+		return nil, err
+	}
+	deleted, err := store.DeleteTemplate(ctx, name, language)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete template: %w", err)
+	}
+	key := templateKey(name, language)
+	if !deleted {
+		return nil, fmt.Errorf("%w: %s has not been customized", ErrTemplateNotFound, key)
+	}
+
+	s.tmplMu.Lock()
+	defer s.tmplMu.Unlock()
+	base, ok := s.base[key]
+	if !ok {
+		delete(s.templates, key)
+		return nil, nil
+	}
+	s.templates[key] = base
+	copied := *base
+	return &copied, nil
+}
+
+// draftTemplate 由修改内容生成模板并校验，名称必须已有内置或模板目录中的版本
+func (s *emailService) draftTemplate(name, language string, draft *TemplateDraft) (*EmailTemplate, error) {
+	if draft == nil || strings.TrimSpace(draft.Subject) == "" ||
+		strings.TrimSpace(draft.HTMLBody) == "" && strings.TrimSpace(draft.TextBody) == "" {
+		return nil, fmt.Errorf("%w: subject and at least one body are required", ErrInvalidTemplate)
+	}
+
+	s.tmplMu.RLock()
+	variables, description, known := s.declaredVariables(name, language)
+	s.tmplMu.RUnlock()
+	if !known {
+		return nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+
+	tmpl := &EmailTemplate{
+		Name:        name,
+		Language:    language,
+		Subject:     draft.Subject,
+		HTMLBody:    draft.HTMLBody,
+		TextBody:    draft.TextBody,
+		Variables:   variables,
+		IsActive:    draft.IsActive == nil || *draft.IsActive,
+		Description: description,
+	}
+	if err := validateTemplate(tmpl); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// declaredVariables 返回名称为name的内置或模板目录中模板声明的变量和描述，调用方需持有读锁
+//
+// 优先使用同语言版本的描述；没有该名称的模板时known为false
+func (s *emailService) declaredVariables(name, language string) (variables map[string]string, description string, known bool) {
+	if base, ok := s.base[templateKey(name, language)]; ok {
+		return base.Variables, base.Description, true
+	}
+	for _, base := range s.base {
+		if base.Name == name {
+			variables, description, known = base.Variables, base.Description, true
+			if base.Language == s.config.DefaultLanguage {
+				break
+			}
+		}
+	}
+	return variables, description, known
+}
+
+// getTemplateStore 返回模板存储，未设置时返回nil
+func (s *emailService) getTemplateStore() TemplateStore {
+	s.tmplMu.RLock()
+	defer s.tmplMu.RUnlock()
+	return s.templateStore
+}
+
+// setTemplateStore 设置模板存储，在加载模板前调用
+func (s *emailService) setTemplateStore(store TemplateStore) {
+	s.tmplMu.Lock()
+	defer s.tmplMu.Unlock()
+	s.templateStore = store
+	s.overridesCheckedAt = time.Time{}
+}
+
+// refreshOverrides 距上次加载超过 templateRefreshInterval 时重新加载模板存储，失败只记录日志
+func (s *emailService) refreshOverrides(ctx context.Context) {
+	s.tmplMu.RLock()
+	due := s.templateStore != nil && time.Since(s.overridesCheckedAt) >= templateRefreshInterval
+	s.tmplMu.RUnlock()
+	if !due {
+		return
+	}
+	if err := s.loadOverrides(ctx); err != nil {
+		log.Printf("Failed to reload email templates: %v", err)
+	}
+}
+
+// loadOverrides 从模板存储加载管理员修改的模板，覆盖内置和模板目录中的同名同语言模板
+//
+// 只加载已有名称的模板，语法错误或使用未声明变量的模板记录日志后跳过
+func (s *emailService) loadOverrides(ctx context.Context) error {
+	s.tmplMu.Lock()
+	store := s.templateStore
+	s.overridesCheckedAt = time.Now()
+	s.tmplMu.Unlock()
+	if store == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, templateStoreTimeout)
+	defer cancel()
+	overrides, err := store.ListTemplates(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.tmplMu.Lock()
+	defer s.tmplMu.Unlock()
+	templates := make(map[string]*EmailTemplate, len(s.base)+len(overrides))
+	for key, tmpl := range s.base {
+		templates[key] = tmpl
+	}
+	for _, override := range overrides {
+		variables, description, known := s.declaredVariables(override.Name, override.Language)
+		if !known {
+			continue
+		}
+		tmpl := *override
+		tmpl.Variables, tmpl.Source = variables, TemplateSourceDatabase
+		if tmpl.Description == "" {
+			tmpl.Description = description
+		}
+		if err := validateTemplate(&tmpl); err != nil {
+			log.Printf("Skipping customized email template %s: %v", templateKey(tmpl.Name, tmpl.Language), err)
+			continue
+		}
+		templates[templateKey(tmpl.Name, tmpl.Language)] = &tmpl
+	}
+	s.templates = templates
+	return nil
+}
+
+// validateTemplate 检查模板语法，并检查模板只引用声明的变量(未声明变量时不限制)
+func validateTemplate(tmpl *EmailTemplate) error {
+	references, err := templateReferences(tmpl)
+	if err != nil {
+		return err
+	}
+	if tmpl.Variables == nil {
+		return nil
+	}
+	var undeclared []string
+	for _, name := range references {
+		if _, ok := tmpl.Variables[name]; !ok {
+			undeclared = append(undeclared, name)
+		}
+	}
+	if len(undeclared) > 0 {
+		return fmt.Errorf("%w: undeclared variables %s", ErrInvalidTemplate, strings.Join(undeclared, ", "))
+	}
+	return nil
+}
+
+// checkVariables 检查渲染变量包含模板引用的全部变量，值可以为空
+func checkVariables(tmpl *EmailTemplate, variables map[string]interface{}) error {
+	references, err := templateReferences(tmpl)
+	if err != nil {
+		return err
+	}
+	var missing []string
+	for _, name := range references {
+		if _, ok := variables[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s", ErrMissingVariables, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// templateReferences 返回模板主题和正文引用的顶层变量，按名称排序
+func templateReferences(tmpl *EmailTemplate) ([]string, error) {
+	seen := make(map[string]bool)
+	parts := []struct{ name, text string }{
+		{"subject", tmpl.Subject},
+		{"html body", tmpl.HTMLBody},
+		{"text body", tmpl.TextBody},
+	}
+	for _, part := range parts {
+		parsed, err := template.New("email").Funcs(i18n.FuncMap(tmpl.Language)).Parse(part.text)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidTemplate, part.name, err)
+		}
+		if parsed.Tree != nil {
+			collectReferences(parsed.Tree.Root, true, seen)
+		}
+	}
+
+	references := make([]string, 0, len(seen))
+	for name := range seen {
+		references = append(references, name)
+	}
+	sort.Strings(references)
+	return references, nil
+}
+
+// collectReferences 收集节点中引用的顶层变量：根作用域中的 .name 和任意位置的 $.name
+//
+// range 和 with 的主体中 . 指向其他值，只收集 $.name
+func collectReferences(node parse.Node, root bool, seen map[string]bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			collectReferences(child, root, seen)
+		}
+	case *parse.ActionNode:
+		collectReferences(n.Pipe, root, seen)
+	case *parse.IfNode:
+		collectReferences(n.Pipe, root, seen)
+		collectReferences(n.List, root, seen)
+		collectReferences(n.ElseList, root, seen)
+	case *parse.RangeNode:
+		collectReferences(n.Pipe, root, seen)
+		collectReferences(n.List, false, seen)
+		collectReferences(n.ElseList, root, seen)
+	case *parse.WithNode:
+		collectReferences(n.Pipe, root, seen)
+		collectReferences(n.List, false, seen)
+		collectReferences(n.ElseList, root, seen)
+	case *parse.TemplateNode:
+		collectReferences(n.Pipe, root, seen)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			for _, arg := range cmd.Args {
+				collectReferences(arg, root, seen)
+			}
+		}
+	case *parse.ChainNode:
+		collectReferences(n.Node, root, seen)
+	case *parse.FieldNode:
+		if root && len(n.Ident) > 0 {
+			seen[n.Ident[0]] = true
+		}
+	case *parse.VariableNode:
+		if len(n.Ident) > 1 && n.Ident[0] == "$" {
+			seen[n.Ident[1]] = true
+		}
+	}
+}
+
+// templateLanguage 标准化模板语言，为空时使用配置的默认语言
+func (s *emailService) templateLanguage(language string) (string, error) {
+	language = i18n.Normalize(language)
+	if language == "" {
+		if s.config.DefaultLanguage != "" {
+			return s.config.DefaultLanguage, nil
+		}
+		return i18n.DefaultLanguage, nil
+	}
+	if !isSupportedTemplateLanguage(language) {
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedLanguage, language)
+	}
+	return language, nil
+}
+
+// templateKey 模板在注册表中的键
+func templateKey(name, language string) string {
+	return name + "_" + language
+}
@@ -0,0 +1,246 @@
+package email
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryTemplateStore 进程内模板存储，测试用
+type memoryTemplateStore struct {
+	mu        sync.Mutex
+	templates map[string]*EmailTemplate
+}
+
+func newMemoryTemplateStore() *memoryTemplateStore {
+	return &memoryTemplateStore{templates: make(map[string]*EmailTemplate)}
+}
+
+func (m *memoryTemplateStore) ListTemplates(ctx context.Context) ([]*EmailTemplate, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	templates := make([]*EmailTemplate, 0, len(m.templates))
+	for _, tmpl := range m.templates {
+		copied := *tmpl
+		templates = append(templates, &copied)
+	}
+	return templates, nil
+}
+
+func (m *memoryTemplateStore) SaveTemplate(ctx context.Context, tmpl *EmailTemplate) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	copied := *tmpl
+	m.templates[templateKey(tmpl.Name, tmpl.Language)] = &copied
+	return nil
+}
+
+func (m *memoryTemplateStore) DeleteTemplate(ctx context.Context, name, language string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := templateKey(name, language)
+	_, ok := m.templates[key]
+	delete(m.templates, key)
+	return ok, nil
+}
+
+func newTemplateTestService(t *testing.T, store TemplateStore) *emailService {
+	config := DefaultEmailConfig()
+	config.TemplateDir = ""
+	service := NewEmailService(config).(*emailService)
+	service.setTemplateStore(store)
+	require.NoError(t, service.LoadTemplates())
+	return service
+}
+
+func TestTemplateReferences(t *testing.T) {
+	references, err := templateReferences(&EmailTemplate{
+		Language: "en-US",
+		Subject:  "{{.app_name}}",
+		HTMLBody: `{{if .username}}Hi {{.username}}{{end}}{{range .items}}{{.name}} {{$.code}}{{end}}`,
+		TextBody: `{{with .details}}{{.ip}}{{end}} {{plural .count "file" "files"}}`,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app_name", "code", "count", "details", "items", "username"}, references)
+
+	_, err = templateReferences(&EmailTemplate{Subject: "{{.app_name"})
+	assert.ErrorIs(t, err, ErrInvalidTemplate)
+}
+
+func TestEmailService_UpdateTemplate(t *testing.T) {
+	store := newMemoryTemplateStore()
+	service := newTemplateTestService(t, store)
+	ctx := context.Background()
+
+	draft := &TemplateDraft{
+		Subject:  "Welcome to {{.app_name}}",
+		HTMLBody: "<p>Hi {{.username}}</p>",
+	}
+	tmpl, err := service.UpdateTemplate(ctx, TemplateWelcome, "en-US", draft, 1)
+	require.NoError(t, err)
+	assert.Equal(t, TemplateSourceDatabase, tmpl.Source)
+	assert.Equal(t, uint(1), tmpl.UpdatedBy)
+	assert.NotNil(t, tmpl.UpdatedAt)
+	assert.Len(t, store.templates, 1)
+
+	current, err := service.GetTemplate(TemplateWelcome, "en-US")
+	require.NoError(t, err)
+	assert.Equal(t, "<p>Hi {{.username}}</p>", current.HTMLBody)
+
+	// 新增支持的语言版本
+	_, err = service.UpdateTemplate(ctx, TemplateWelcome, "ja-JP", draft, 1)
+	require.NoError(t, err)
+
+	_, err = service.UpdateTemplate(ctx, TemplateWelcome, "en-US", &TemplateDraft{Subject: "Hi", TextBody: "{{.password}}"}, 1)
+	assert.ErrorIs(t, err, ErrInvalidTemplate)
+	_, err = service.UpdateTemplate(ctx, TemplateWelcome, "en-US", &TemplateDraft{Subject: "Hi {{.username"}, 1)
+	assert.ErrorIs(t, err, ErrInvalidTemplate)
+	_, err = service.UpdateTemplate(ctx, TemplateWelcome, "en-US", &TemplateDraft{Subject: "Hi"}, 1)
+	assert.ErrorIs(t, err, ErrInvalidTemplate)
+	_, err = service.UpdateTemplate(ctx, "unknown", "en-US", draft, 1)
+	assert.ErrorIs(t, err, ErrTemplateNotFound)
+	_, err = service.UpdateTemplate(ctx, TemplateWelcome, "fr-FR", draft, 1)
+	assert.ErrorIs(t, err, ErrUnsupportedLanguage)
+
+	withoutStore := newTemplateTestService(t, nil)
+	_, err = withoutStore.UpdateTemplate(ctx, TemplateWelcome, "en-US", draft, 1)
+	assert.ErrorIs(t, err, ErrTemplateStoreUnavailable)
+}
+
+func TestEmailService_ResetTemplate(t *testing.T) {
+	store := newMemoryTemplateStore()
+	service := newTemplateTestService(t, store)
+	ctx := context.Background()
+	draft := &TemplateDraft{Subject: "Hi {{.username}}", TextBody: "Hi"}
+
+	builtin, err := service.GetTemplate(TemplateWelcome, "en-US")
+	require.NoError(t, err)
+	_, err = service.UpdateTemplate(ctx, TemplateWelcome, "en-US", draft, 1)
+	require.NoError(t, err)
+
+	restored, err := service.ResetTemplate(ctx, TemplateWelcome, "en-US")
+	require.NoError(t, err)
+	assert.Equal(t, builtin.Subject, restored.Subject)
+	assert.Equal(t, TemplateSourceDefault, restored.Source)
+	assert.Empty(t, store.templates)
+
+	_, err = service.ResetTemplate(ctx, TemplateWelcome, "en-US")
+	assert.ErrorIs(t, err, ErrTemplateNotFound)
+
+	// 只存在于修改中的语言版本被删除
+	_, err = service.UpdateTemplate(ctx, TemplateWelcome, "ja-JP", draft, 1)
+	require.NoError(t, err)
+	restored, err = service.ResetTemplate(ctx, TemplateWelcome, "ja-JP")
+	require.NoError(t, err)
+	assert.Nil(t, restored)
+	_, err = service.GetTemplateVariant(TemplateWelcome, "ja-JP")
+	assert.ErrorIs(t, err, ErrTemplateNotFound)
+}
+
+func TestEmailService_PreviewTemplate(t *testing.T) {
+	service := newTemplateTestService(t, nil)
+
+	rendered, err := service.PreviewTemplate(TemplateWelcome, "en-US", nil, nil)
+	require.NoError(t, err)
+	assert.Contains(t, rendered.HTMLBody, "[username]")
+	assert.Equal(t, "[username]", rendered.Variables["username"])
+
+	draft := &TemplateDraft{Subject: "Hi {{.username}}", TextBody: "Welcome to {{.app_name}}"}
+	rendered, err = service.PreviewTemplate(TemplateWelcome, "en-US", draft, map[string]interface{}{
+		"username": "alice", "app_name": "CloudPan",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Hi alice", rendered.Subject)
+	assert.Equal(t, "Welcome to CloudPan", rendered.TextBody)
+
+	_, err = service.PreviewTemplate(TemplateWelcome, "en-US", draft, map[string]interface{}{"username": "alice"})
+	assert.ErrorIs(t, err, ErrMissingVariables)
+
+	// 预览不保存
+	current, err := service.GetTemplate(TemplateWelcome, "en-US")
+	require.NoError(t, err)
+	assert.NotEqual(t, "Hi {{.username}}", current.Subject)
+}
+
+func TestEmailService_SendTemplateEmail_MissingVariables(t *testing.T) {
+	service := newTemplateTestService(t, nil)
+
+	err := service.SendTemplateEmail(context.Background(), TemplateWelcome, []string{"alice@example.com"}, map[string]interface{}{
+		"app_name": "CloudPan",
+	})
+	assert.ErrorIs(t, err, ErrMissingVariables)
+}
+
+func TestEmailService_LoadOverrides(t *testing.T) {
+	store := newMemoryTemplateStore()
+	require.NoError(t, store.SaveTemplate(context.Background(), &EmailTemplate{
+		Name: TemplateWelcome, Language: "en-US", Subject: "Custom {{.username}}", TextBody: "Hi", IsActive: true,
+	}))
+	// 使用未声明变量和未知名称的模板被跳过
+	require.NoError(t, store.SaveTemplate(context.Background(), &EmailTemplate{
+		Name: TemplateWelcome, Language: "zh-CN", Subject: "{{.password}}", TextBody: "Hi", IsActive: true,
+	}))
+	require.NoError(t, store.SaveTemplate(context.Background(), &EmailTemplate{
+		Name: "register", Language: "zh-CN", Subject: "Hi", TextBody: "Hi", IsActive: true,
+	}))
+
+	service := newTemplateTestService(t, store)
+	tmpl, err := service.GetTemplate(TemplateWelcome, "en-US")
+	require.NoError(t, err)
+	assert.Equal(t, "Custom {{.username}}", tmpl.Subject)
+	assert.Equal(t, TemplateSourceDatabase, tmpl.Source)
+	assert.NotEmpty(t, tmpl.Variables)
+
+	tmpl, err = service.GetTemplate(TemplateWelcome, "zh-CN")
+	require.NoError(t, err)
+	assert.Equal(t, TemplateSourceDefault, tmpl.Source)
+	_, err = service.GetTemplate("register", "zh-CN")
+	assert.Error(t, err)
+}
+
+func TestEmailService_LoadTemplatesFromDir(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplateFile(t, dir, "en-US", "welcome.subject", "Hello from files, {{.username}}\n")
+	writeTemplateFile(t, dir, "en-US", "digest.subject", "{{.count}} new files")
+	writeTemplateFile(t, dir, "en-US", "digest.txt", "You have {{.count}} new files")
+	writeTemplateFile(t, dir, "en-US", "broken.subject", "{{.count")
+	writeTemplateFile(t, dir, "en-US", "broken.txt", "body")
+	writeTemplateFile(t, dir, "en-US", "no_body.subject", "subject only")
+	writeTemplateFile(t, dir, "fr-FR", "welcome.subject", "Bonjour")
+
+	config := DefaultEmailConfig()
+	config.TemplateDir = dir
+	service := NewEmailService(config).(*emailService)
+	require.NoError(t, service.LoadTemplates())
+
+	welcome, err := service.GetTemplate(TemplateWelcome, "en-US")
+	require.NoError(t, err)
+	assert.Equal(t, "Hello from files, {{.username}}", welcome.Subject)
+	assert.Contains(t, welcome.HTMLBody, "{{.username}}") // 沿用内置模板的正文
+	assert.Equal(t, TemplateSourceFile, welcome.Source)
+
+	digest, err := service.GetTemplate("digest", "en-US")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"count": ""}, digest.Variables)
+
+	_, err = service.GetTemplate("broken", "en-US")
+	assert.Error(t, err)
+	_, err = service.GetTemplate("no_body", "en-US")
+	assert.Error(t, err)
+	_, err = service.GetTemplateVariant(TemplateWelcome, "fr-FR")
+	assert.ErrorIs(t, err, ErrUnsupportedLanguage)
+
+	config.TemplateDir = filepath.Join(dir, "missing")
+	require.NoError(t, NewEmailService(config).LoadTemplates())
+}
+
+func writeTemplateFile(t *testing.T, dir, language, name, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, language), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, language, name), []byte(content), 0o644))
+}
@@ -1,7 +1,56 @@
 package email
 
+// defaultTemplateVariables 内置模板可以使用的变量及说明，模板目录和管理员修改的同名模板只能使用这些变量
+var defaultTemplateVariables = map[string]map[string]string{
+	TemplateVerificationCode: {
+		"app_name":   "应用名称",
+		"code":       "验证码",
+		"expires_in": "验证码有效期(分钟)",
+	},
+	TemplatePasswordReset: {
+		"app_name":   "应用名称",
+		"reset_url":  "密码重置链接",
+		"expires_in": "重置链接有效期(小时)",
+	},
+	TemplateWelcome: {
+		"app_name": "应用名称",
+		"username": "用户名",
+	},
+	TemplateSecurityAlert: {
+		"app_name":   "应用名称",
+		"alert_type": "警告类型",
+		"details":    "警告详情",
+		"timestamp":  "发生时间",
+	},
+	TemplateForcedPasswordReset: {
+		"app_name":  "应用名称",
+		"username":  "用户名",
+		"reason":    "管理员填写的原因，可能为空",
+		"reset_url": "密码重置页面链接，可能为空",
+	},
+	TemplateInactivityWarning: {
+		"app_name":    "应用名称",
+		"username":    "用户名",
+		"step":        "即将执行的处置：downgrade降低存储配额、freeze冻结账户、delete计划删除账户",
+		"action_date": "执行处置的日期",
+		"days_left":   "距执行处置的天数",
+		"final":       "是否为最后一次提醒",
+		"login_url":   "登录页面链接，可能为空",
+	},
+}
+
 // getDefaultTemplates 获取默认邮件模板
 func (s *emailService) getDefaultTemplates() []*EmailTemplate {
+	templates := builtinTemplates()
+	for _, tmpl := range templates {
+		tmpl.Variables = defaultTemplateVariables[tmpl.Name]
+		tmpl.Source = TemplateSourceDefault
+	}
+	return templates
+}
+
+// builtinTemplates 内置模板的各语言版本
+func builtinTemplates() []*EmailTemplate {
 	return []*EmailTemplate{
 		// 验证码模板 - 中文
 		{
@@ -43,6 +92,86 @@ func (s *emailService) getDefaultTemplates() []*EmailTemplate {
 			IsActive:    true,
 			Description: "安全警告模板",
 		},
+		// 强制重置密码模板 - 中文
+		{
+			Name:        TemplateForcedPasswordReset,
+			Language:    "zh-CN",
+			Subject:     "【{{.app_name}}】请立即重置您的密码",
+			HTMLBody:    getForcedPasswordResetHTML_ZH(),
+			TextBody:    getForcedPasswordResetText_ZH(),
+			IsActive:    true,
+			Description: "管理员强制重置密码模板",
+		},
+		// 长期未登录提醒模板 - 中文
+		{
+			Name:        TemplateInactivityWarning,
+			Language:    "zh-CN",
+			Subject:     "【{{.app_name}}】{{if .final}}最后提醒：{{end}}您的账户长期未登录",
+			HTMLBody:    getInactivityWarningHTML_ZH(),
+			TextBody:    getInactivityWarningText_ZH(),
+			IsActive:    true,
+			Description: "长期未登录账户处置提醒模板",
+		},
+		// 验证码模板 - 英文
+		{
+			Name:        TemplateVerificationCode,
+			Language:    "en-US",
+			Subject:     "[{{.app_name}}] Your verification code",
+			HTMLBody:    getVerificationCodeHTML_EN(),
+			TextBody:    getVerificationCodeText_EN(),
+			IsActive:    true,
+			Description: "邮箱验证码模板(英文)",
+		},
+		// 密码重置模板 - 英文
+		{
+			Name:        TemplatePasswordReset,
+			Language:    "en-US",
+			Subject:     "[{{.app_name}}] Reset your password",
+			HTMLBody:    getPasswordResetHTML_EN(),
+			TextBody:    getPasswordResetText_EN(),
+			IsActive:    true,
+			Description: "密码重置模板(英文)",
+		},
+		// 欢迎邮件模板 - 英文
+		{
+			Name:        TemplateWelcome,
+			Language:    "en-US",
+			Subject:     "Welcome to {{.app_name}}!",
+			HTMLBody:    getWelcomeHTML_EN(),
+			TextBody:    getWelcomeText_EN(),
+			IsActive:    true,
+			Description: "欢迎邮件模板(英文)",
+		},
+		// 安全警告模板 - 英文
+		{
+			Name:        TemplateSecurityAlert,
+			Language:    "en-US",
+			Subject:     "[{{.app_name}}] Security alert",
+			HTMLBody:    getSecurityAlertHTML_EN(),
+			TextBody:    getSecurityAlertText_EN(),
+			IsActive:    true,
+			Description: "安全警告模板(英文)",
+		},
+		// 强制重置密码模板 - 英文
+		{
+			Name:        TemplateForcedPasswordReset,
+			Language:    "en-US",
+			Subject:     "[{{.app_name}}] Please reset your password now",
+			HTMLBody:    getForcedPasswordResetHTML_EN(),
+			TextBody:    getForcedPasswordResetText_EN(),
+			IsActive:    true,
+			Description: "管理员强制重置密码模板(英文)",
+		},
+		// 长期未登录提醒模板 - 英文
+		{
+			Name:        TemplateInactivityWarning,
+			Language:    "en-US",
+			Subject:     "[{{.app_name}}] {{if .final}}Final notice: {{end}}Your account has been inactive",
+			HTMLBody:    getInactivityWarningHTML_EN(),
+			TextBody:    getInactivityWarningText_EN(),
+			IsActive:    true,
+			Description: "长期未登录账户处置提醒模板(英文)",
+		},
 	}
 }
 
@@ -233,3 +362,98 @@ func getSecurityAlertText_ZH() string {
 此邮件由系统自动发送，请勿回复
 © {{.app_name}} 安全中心`
 }
+
+// 强制重置密码HTML模板
+func getForcedPasswordResetHTML_ZH() string {
+	return `<!DOCTYPE html>
+<html><head><meta charset="UTF-8"><title>请重置密码</title>
+<style>
+body{font-family:'Microsoft YaHei',Arial;margin:0;padding:20px;background:#f5f5f5}
+.container{max-width:600px;margin:0 auto;background:#fff;border-radius:8px;box-shadow:0 2px 10px rgba(0,0,0,0.1)}
+.header{background:linear-gradient(135deg,#ff4757 0%,#c44569 100%);color:white;padding:30px;text-align:center}
+.content{padding:40px 30px}
+.alert{background:#f8d7da;border:1px solid #f5c6cb;border-radius:4px;padding:15px;margin:20px 0;color:#721c24}
+.button{display:inline-block;background:#ff4757;color:white;padding:12px 30px;text-decoration:none;border-radius:4px}
+.footer{background:#f8f9fa;padding:20px;text-align:center;color:#666;font-size:12px}
+</style></head>
+<body>
+<div class="container">
+<div class="header"><h1>🔒 请重置密码</h1><p>{{.app_name}} 安全中心</p></div>
+<div class="content">
+<h2>{{.username}}，您好</h2>
+<p>出于安全原因，管理员已要求您重置账户密码。您已在所有设备上退出登录，设置新密码前无法登录。</p>
+{{if .reason}}<div class="alert"><p><strong>原因：</strong> {{.reason}}</p></div>{{end}}
+{{if .reset_url}}<p style="text-align:center"><a class="button" href="{{.reset_url}}">重置密码</a></p>{{end}}
+<p>请在登录页点击"忘记密码"，通过邮箱验证码设置新密码。</p>
+<ul><li>请不要重复使用以前的密码</li><li>如有疑问，请联系管理员</li></ul>
+</div>
+<div class="footer"><p>此邮件由系统自动发送，请勿回复</p><p>&copy; {{.app_name}} 安全中心</p></div>
+</div></body></html>`
+}
+
+// 强制重置密码文本模板
+func getForcedPasswordResetText_ZH() string {
+	return `{{.app_name}} - 请重置密码
+
+{{.username}}，您好
+
+出于安全原因，管理员已要求您重置账户密码。您已在所有设备上退出登录，设置新密码前无法登录。
+{{if .reason}}
+原因：{{.reason}}
+{{end}}
+请在登录页点击"忘记密码"，通过邮箱验证码设置新密码。{{if .reset_url}}
+重置地址：{{.reset_url}}{{end}}
+
+- 请不要重复使用以前的密码
+- 如有疑问，请联系管理员
+
+此邮件由系统自动发送，请勿回复
+© {{.app_name}} 安全中心`
+}
+
+// 长期未登录提醒HTML模板
+func getInactivityWarningHTML_ZH() string {
+	return `<!DOCTYPE html>
+<html><head><meta charset="UTF-8"><title>账户长期未登录</title>
+<style>
+body{font-family:'Microsoft YaHei',Arial;margin:0;padding:20px;background:#f5f5f5}
+.container{max-width:600px;margin:0 auto;background:#fff;border-radius:8px;box-shadow:0 2px 10px rgba(0,0,0,0.1)}
+.header{background:linear-gradient(135deg,#f7b733 0%,#fc4a1a 100%);color:white;padding:30px;text-align:center}
+.content{padding:40px 30px}
+.alert{background:#fff3cd;border:1px solid #ffeaa7;border-radius:4px;padding:15px;margin:20px 0;color:#856404}
+.button{display:inline-block;background:#fc4a1a;color:white;padding:12px 30px;text-decoration:none;border-radius:4px}
+.footer{background:#f8f9fa;padding:20px;text-align:center;color:#666;font-size:12px}
+</style></head>
+<body>
+<div class="container">
+<div class="header"><h1>⏰ {{if .final}}最后提醒{{else}}账户长期未登录{{end}}</h1><p>{{.app_name}}</p></div>
+<div class="content">
+<h2>{{.username}}，您好</h2>
+<p>您的账户已经很长时间没有登录了。</p>
+<div class="alert"><p>如果您在 <strong>{{.action_date}}</strong>（{{.days_left}}天后）之前仍未登录，
+{{if eq .step "downgrade"}}账户的存储配额将被降低，超出部分的文件仍可下载和删除，但不能再上传新文件。
+{{else if eq .step "freeze"}}账户将被冻结，分享链接将无法访问，重新登录后即可恢复。
+{{else}}账户将被计划删除，之后的一段时间内仍可登录恢复，超过期限后账户和全部文件将被彻底删除。{{end}}</p></div>
+{{if .login_url}}<p style="text-align:center"><a class="button" href="{{.login_url}}">立即登录</a></p>{{end}}
+<p>登录一次即可保留您的账户，无需其他操作。</p>
+</div>
+<div class="footer"><p>此邮件由系统自动发送，请勿回复</p><p>&copy; {{.app_name}}</p></div>
+</div></body></html>`
+}
+
+// 长期未登录提醒文本模板
+func getInactivityWarningText_ZH() string {
+	return `{{.app_name}} - {{if .final}}最后提醒：{{end}}账户长期未登录
+
+{{.username}}，您好
+
+您的账户已经很长时间没有登录了。如果您在 {{.action_date}}（{{.days_left}}天后）之前仍未登录，
+{{if eq .step "downgrade"}}账户的存储配额将被降低，超出部分的文件仍可下载和删除，但不能再上传新文件。
+{{else if eq .step "freeze"}}账户将被冻结，分享链接将无法访问，重新登录后即可恢复。
+{{else}}账户将被计划删除，之后的一段时间内仍可登录恢复，超过期限后账户和全部文件将被彻底删除。{{end}}
+登录一次即可保留您的账户，无需其他操作。{{if .login_url}}
+登录地址：{{.login_url}}{{end}}
+
+此邮件由系统自动发送，请勿回复
+© {{.app_name}}`
+}
@@ -0,0 +1,286 @@
+package email
+
+// 英文模板中的数量使用 i18n.FuncMap 的 count 函数，如 {{count .expires_in "minute" "minutes"}}
+
+// 验证码HTML模板(英文)
+func getVerificationCodeHTML_EN() string {
+	return `<!DOCTYPE html>
+<html><head><meta charset="UTF-8"><title>Verification code</title>
+<style>
+body{font-family:Arial,Helvetica,sans-serif;margin:0;padding:20px;background:#f5f5f5}
+.container{max-width:600px;margin:0 auto;background:#fff;border-radius:8px;box-shadow:0 2px 10px rgba(0,0,0,0.1)}
+.header{background:linear-gradient(135deg,#667eea 0%,#764ba2 100%);color:white;padding:30px;text-align:center}
+.content{padding:40px 30px}
+.code-box{background:#f8f9fa;border:2px dashed #007bff;border-radius:8px;padding:20px;text-align:center;margin:20px 0}
+.code{font-size:32px;font-weight:bold;color:#007bff;letter-spacing:8px;font-family:monospace}
+.footer{background:#f8f9fa;padding:20px;text-align:center;color:#666;font-size:12px}
+.warning{background:#fff3cd;border:1px solid #ffeaa7;border-radius:4px;padding:15px;margin:20px 0;color:#856404}
+</style></head>
+<body>
+<div class="container">
+<div class="header"><h1>{{.app_name}}</h1><p>Verification code</p></div>
+<div class="content">
+<h2>Verify your email address</h2>
+<p>Hello! Thanks for signing up for {{.app_name}}. Use the code below to verify your email address:</p>
+<div class="code-box"><div class="code">{{.code}}</div><p style="margin:10px 0 0 0;color:#666">Verification code</p></div>
+<div class="warning"><strong>Please note:</strong>
+<ul><li>This code expires in {{count .expires_in "minute" "minutes"}}</li><li>Never share this code with anyone</li><li>If you did not request this code, you can ignore this email</li></ul>
+</div>
+</div>
+<div class="footer"><p>This is an automated message, please do not reply</p><p>&copy; The {{.app_name}} Team</p></div>
+</div></body></html>`
+}
+
+// 验证码文本模板(英文)
+func getVerificationCodeText_EN() string {
+	return `{{.app_name}} - Verification code
+
+Hello! Thanks for signing up for {{.app_name}}. Use the code below to verify your email address:
+
+Code: {{.code}}
+
+Please note:
+- This code expires in {{count .expires_in "minute" "minutes"}}
+- Never share this code with anyone
+- If you did not request this code, you can ignore this email
+
+This is an automated message, please do not reply
+© The {{.app_name}} Team`
+}
+
+// 密码重置HTML模板(英文)
+func getPasswordResetHTML_EN() string {
+	return `<!DOCTYPE html>
+<html><head><meta charset="UTF-8"><title>Password reset</title>
+<style>
+body{font-family:Arial,Helvetica,sans-serif;margin:0;padding:20px;background:#f5f5f5}
+.container{max-width:600px;margin:0 auto;background:#fff;border-radius:8px;box-shadow:0 2px 10px rgba(0,0,0,0.1)}
+.header{background:linear-gradient(135deg,#ff6b6b 0%,#feca57 100%);color:white;padding:30px;text-align:center}
+.content{padding:40px 30px}
+.btn{display:inline-block;background:#007bff;color:white;padding:15px 30px;text-decoration:none;border-radius:5px;font-weight:bold;margin:20px 0}
+.footer{background:#f8f9fa;padding:20px;text-align:center;color:#666;font-size:12px}
+.warning{background:#f8d7da;border:1px solid #f5c6cb;border-radius:4px;padding:15px;margin:20px 0;color:#721c24}
+</style></head>
+<body>
+<div class="container">
+<div class="header"><h1>{{.app_name}}</h1><p>Password reset request</p></div>
+<div class="content">
+<h2>Reset your password</h2>
+<p>We received a request to reset your password. Click the button below to choose a new one:</p>
+<div style="text-align:center;margin:30px 0"><a href="{{.reset_url}}" class="btn">Reset password</a></div>
+<div class="warning"><strong>Security reminder:</strong>
+<ul><li>This link expires in {{count .expires_in "hour" "hours"}}</li><li>If you did not request a password reset, you can ignore this email</li><li>For your account's safety, do not share this link with anyone</li></ul>
+</div>
+</div>
+<div class="footer"><p>This is an automated message, please do not reply</p><p>&copy; The {{.app_name}} Team</p></div>
+</div></body></html>`
+}
+
+// 密码重置文本模板(英文)
+func getPasswordResetText_EN() string {
+	return `{{.app_name}} - Password reset request
+
+We received a request to reset your password.
+
+Visit the link below to choose a new password:
+{{.reset_url}}
+
+Security reminder:
+- This link expires in {{count .expires_in "hour" "hours"}}
+- If you did not request a password reset, you can ignore this email
+- For your account's safety, do not share this link with anyone
+
+This is an automated message, please do not reply
+© The {{.app_name}} Team`
+}
+
+// 欢迎邮件HTML模板(英文)
+func getWelcomeHTML_EN() string {
+	return `<!DOCTYPE html>
+<html><head><meta charset="UTF-8"><title>Welcome</title>
+<style>
+body{font-family:Arial,Helvetica,sans-serif;margin:0;padding:20px;background:#f5f5f5}
+.container{max-width:600px;margin:0 auto;background:#fff;border-radius:8px;box-shadow:0 2px 10px rgba(0,0,0,0.1)}
+.header{background:linear-gradient(135deg,#4facfe 0%,#00f2fe 100%);color:white;padding:30px;text-align:center}
+.content{padding:40px 30px}
+.feature{background:#f8f9fa;padding:20px;border-radius:8px;margin:15px 0}
+.footer{background:#f8f9fa;padding:20px;text-align:center;color:#666;font-size:12px}
+</style></head>
+<body>
+<div class="container">
+<div class="header"><h1>Welcome to {{.app_name}}!</h1><p>Your cloud storage journey starts here</p></div>
+<div class="content">
+<h2>Welcome, {{.username}}!</h2>
+<p>Thanks for signing up for {{.app_name}}! We're glad to have you with us.</p>
+<h3>Get started with:</h3>
+<div class="feature"><h4>📁 File management</h4><p>Upload, download and share your files in many formats</p></div>
+<div class="feature"><h4>👥 Team collaboration</h4><p>Share folders with your team and work together in real time</p></div>
+<div class="feature"><h4>💬 Instant messaging</h4><p>Chat with your team in real time and get more done</p></div>
+<div class="feature"><h4>🔒 Secure storage</h4><p>Enterprise-grade protection keeps your data safe</p></div>
+</div>
+<div class="footer"><p>This is an automated message, please do not reply</p><p>&copy; The {{.app_name}} Team</p></div>
+</div></body></html>`
+}
+
+// 欢迎邮件文本模板(英文)
+func getWelcomeText_EN() string {
+	return `Welcome to {{.app_name}}!
+
+Welcome, {{.username}}!
+
+Thanks for signing up for {{.app_name}}! We're glad to have you with us.
+
+Get started with:
+📁 File management - upload, download and share your files
+👥 Team collaboration - share folders with your team and work together in real time
+💬 Instant messaging - chat with your team in real time and get more done
+🔒 Secure storage - enterprise-grade protection keeps your data safe
+
+This is an automated message, please do not reply
+© The {{.app_name}} Team`
+}
+
+// 安全警告HTML模板(英文)
+func getSecurityAlertHTML_EN() string {
+	return `<!DOCTYPE html>
+<html><head><meta charset="UTF-8"><title>Security alert</title>
+<style>
+body{font-family:Arial,Helvetica,sans-serif;margin:0;padding:20px;background:#f5f5f5}
+.container{max-width:600px;margin:0 auto;background:#fff;border-radius:8px;box-shadow:0 2px 10px rgba(0,0,0,0.1)}
+.header{background:linear-gradient(135deg,#ff4757 0%,#c44569 100%);color:white;padding:30px;text-align:center}
+.content{padding:40px 30px}
+.alert{background:#f8d7da;border:1px solid #f5c6cb;border-radius:4px;padding:15px;margin:20px 0;color:#721c24}
+.footer{background:#f8f9fa;padding:20px;text-align:center;color:#666;font-size:12px}
+</style></head>
+<body>
+<div class="container">
+<div class="header"><h1>⚠️ Security alert</h1><p>{{.app_name}} Security Center</p></div>
+<div class="content">
+<h2>Security event detected: {{.alert_type}}</h2>
+<p>We detected a security event on your account. Details:</p>
+<div class="alert">
+<h4>Event details:</h4>
+<p><strong>Time:</strong> {{.timestamp}}</p>
+<p><strong>Type:</strong> {{.alert_type}}</p>
+</div>
+<h3>Recommended actions:</h3>
+<ul><li>Change your password immediately</li><li>Review recent activity on your account</li><li>Turn on two-factor authentication</li><li>If this wasn't you, contact us right away</li></ul>
+</div>
+<div class="footer"><p>This is an automated message, please do not reply</p><p>&copy; {{.app_name}} Security Center</p></div>
+</div></body></html>`
+}
+
+// 安全警告文本模板(英文)
+func getSecurityAlertText_EN() string {
+	return `{{.app_name}} - Security alert
+
+Security event detected: {{.alert_type}}
+
+We detected a security event on your account:
+Time: {{.timestamp}}
+Type: {{.alert_type}}
+
+Recommended actions:
+- Change your password immediately
+- Review recent activity on your account
+- Turn on two-factor authentication
+- If this wasn't you, contact us right away
+
+This is an automated message, please do not reply
+© {{.app_name}} Security Center`
+}
+
+// 强制重置密码HTML模板(英文)
+func getForcedPasswordResetHTML_EN() string {
+	return `<!DOCTYPE html>
+<html><head><meta charset="UTF-8"><title>Please reset your password</title>
+<style>
+body{font-family:Arial,Helvetica,sans-serif;margin:0;padding:20px;background:#f5f5f5}
+.container{max-width:600px;margin:0 auto;background:#fff;border-radius:8px;box-shadow:0 2px 10px rgba(0,0,0,0.1)}
+.header{background:linear-gradient(135deg,#ff4757 0%,#c44569 100%);color:white;padding:30px;text-align:center}
+.content{padding:40px 30px}
+.alert{background:#f8d7da;border:1px solid #f5c6cb;border-radius:4px;padding:15px;margin:20px 0;color:#721c24}
+.button{display:inline-block;background:#ff4757;color:white;padding:12px 30px;text-decoration:none;border-radius:4px}
+.footer{background:#f8f9fa;padding:20px;text-align:center;color:#666;font-size:12px}
+</style></head>
+<body>
+<div class="container">
+<div class="header"><h1>🔒 Please reset your password</h1><p>{{.app_name}} Security Center</p></div>
+<div class="content">
+<h2>Hello {{.username}},</h2>
+<p>For security reasons, an administrator has required you to reset your account password. You have been signed out on all devices and cannot sign in until you set a new password.</p>
+{{if .reason}}<div class="alert"><p><strong>Reason:</strong> {{.reason}}</p></div>{{end}}
+{{if .reset_url}}<p style="text-align:center"><a class="button" href="{{.reset_url}}">Reset password</a></p>{{end}}
+<p>On the sign-in page, click "Forgot password" to set a new password with an email verification code.</p>
+<ul><li>Do not reuse a previous password</li><li>If you have any questions, contact your administrator</li></ul>
+</div>
+<div class="footer"><p>This is an automated message, please do not reply</p><p>&copy; {{.app_name}} Security Center</p></div>
+</div></body></html>`
+}
+
+// 强制重置密码文本模板(英文)
+func getForcedPasswordResetText_EN() string {
+	return `{{.app_name}} - Please reset your password
+
+Hello {{.username}},
+
+For security reasons, an administrator has required you to reset your account password. You have been signed out on all devices and cannot sign in until you set a new password.
+{{if .reason}}
+Reason: {{.reason}}
+{{end}}
+On the sign-in page, click "Forgot password" to set a new password with an email verification code.{{if .reset_url}}
+Reset link: {{.reset_url}}{{end}}
+
+- Do not reuse a previous password
+- If you have any questions, contact your administrator
+
+This is an automated message, please do not reply
+© {{.app_name}} Security Center`
+}
+
+// 长期未登录提醒HTML模板(英文)
+func getInactivityWarningHTML_EN() string {
+	return `<!DOCTYPE html>
+<html><head><meta charset="UTF-8"><title>Your account has been inactive</title>
+<style>
+body{font-family:Arial,Helvetica,sans-serif;margin:0;padding:20px;background:#f5f5f5}
+.container{max-width:600px;margin:0 auto;background:#fff;border-radius:8px;box-shadow:0 2px 10px rgba(0,0,0,0.1)}
+.header{background:linear-gradient(135deg,#f7b733 0%,#fc4a1a 100%);color:white;padding:30px;text-align:center}
+.content{padding:40px 30px}
+.alert{background:#fff3cd;border:1px solid #ffeaa7;border-radius:4px;padding:15px;margin:20px 0;color:#856404}
+.button{display:inline-block;background:#fc4a1a;color:white;padding:12px 30px;text-decoration:none;border-radius:4px}
+.footer{background:#f8f9fa;padding:20px;text-align:center;color:#666;font-size:12px}
+</style></head>
+<body>
+<div class="container">
+<div class="header"><h1>⏰ {{if .final}}Final notice{{else}}Your account has been inactive{{end}}</h1><p>{{.app_name}}</p></div>
+<div class="content">
+<h2>Hello {{.username}},</h2>
+<p>You haven't signed in to your account for a long time.</p>
+<div class="alert"><p>If you don't sign in before <strong>{{.action_date}}</strong> (in {{count .days_left "day" "days"}}),
+{{if eq .step "downgrade"}}your storage quota will be reduced. Files over the quota can still be downloaded and deleted, but you won't be able to upload new files.
+{{else if eq .step "freeze"}}your account will be frozen and your share links will stop working until you sign in again.
+{{else}}your account will be scheduled for deletion. You can still sign in to restore it for a limited time, after which your account and all of your files will be permanently deleted.{{end}}</p></div>
+{{if .login_url}}<p style="text-align:center"><a class="button" href="{{.login_url}}">Sign in now</a></p>{{end}}
+<p>Signing in once is all it takes to keep your account.</p>
+</div>
+<div class="footer"><p>This is an automated message, please do not reply</p><p>&copy; {{.app_name}}</p></div>
+</div></body></html>`
+}
+
+// 长期未登录提醒文本模板(英文)
+func getInactivityWarningText_EN() string {
+	return `{{.app_name}} - {{if .final}}Final notice: {{end}}Your account has been inactive
+
+Hello {{.username}},
+
+You haven't signed in to your account for a long time. If you don't sign in before {{.action_date}} (in {{count .days_left "day" "days"}}),
+{{if eq .step "downgrade"}}your storage quota will be reduced. Files over the quota can still be downloaded and deleted, but you won't be able to upload new files.
+{{else if eq .step "freeze"}}your account will be frozen and your share links will stop working until you sign in again.
+{{else}}your account will be scheduled for deletion. You can still sign in to restore it for a limited time, after which your account and all of your files will be permanently deleted.{{end}}
+Signing in once is all it takes to keep your account.{{if .login_url}}
+Sign in: {{.login_url}}{{end}}
+
+This is an automated message, please do not reply
+© {{.app_name}}`
+}
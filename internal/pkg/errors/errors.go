@@ -103,6 +103,8 @@ var (
 	ErrOperationNotAllowed = errors.New("operation not allowed")
 	// ErrQuotaExceeded 配额超出
 	ErrQuotaExceeded = errors.New("quota exceeded")
+	// ErrTooManyRequests 并发或频率超出限制
+	ErrTooManyRequests = errors.New("too many requests")
 )
 
 // 网络和I/O错误
@@ -196,6 +198,11 @@ func IsValidationError(err error) bool {
 		errors.Is(err, ErrInvalidFormat)
 }
 
+// IsRateLimitError 检查是否为并发或频率超限错误
+func IsRateLimitError(err error) bool {
+	return err != nil && errors.Is(err, ErrTooManyRequests)
+}
+
 // IsRetryableError 检查错误是否可重试
 func IsRetryableError(err error) bool {
 	if err == nil {
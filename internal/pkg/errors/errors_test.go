@@ -116,6 +116,7 @@ func TestBusinessLogicErrors(t *testing.T) {
 		{"ErrPermissionDenied", ErrPermissionDenied},
 		{"ErrOperationNotAllowed", ErrOperationNotAllowed},
 		{"ErrQuotaExceeded", ErrQuotaExceeded},
+		{"ErrTooManyRequests", ErrTooManyRequests},
 	}
 
 	for _, tt := range tests {
@@ -433,6 +434,45 @@ func TestIsPermissionError(t *testing.T) {
 	}
 }
 
+// TestIsRateLimitError 测试并发或频率超限错误检查函数
+func TestIsRateLimitError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{
+			name:     "nil error",
+			err:      nil,
+			expected: false,
+		},
+		{
+			name:     "too many requests",
+			err:      ErrTooManyRequests,
+			expected: true,
+		},
+		{
+			name:     "wrapped too many requests",
+			err:      WrapError(ErrTooManyRequests, "wrapped"),
+			expected: true,
+		},
+		{
+			name:     "quota exceeded",
+			err:      ErrQuotaExceeded,
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := IsRateLimitError(tt.err)
+			if result != tt.expected {
+				t.Errorf("IsRateLimitError() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
 // TestIsValidationError 测试验证错误检查函数
 func TestIsValidationError(t *testing.T) {
 	tests := []struct {
@@ -570,6 +610,7 @@ func TestErrorConstistency(t *testing.T) {
 		"ErrPermissionDenied":         ErrPermissionDenied,
 		"ErrOperationNotAllowed":      ErrOperationNotAllowed,
 		"ErrQuotaExceeded":            ErrQuotaExceeded,
+		"ErrTooManyRequests":          ErrTooManyRequests,
 		"ErrNetworkTimeout":           ErrNetworkTimeout,
 		"ErrFileNotFound":             ErrFileNotFound,
 		"ErrFileCorrupted":            ErrFileCorrupted,
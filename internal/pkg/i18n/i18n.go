@@ -0,0 +1,203 @@
+// Package i18n 提供API和邮件共用的语言处理工具
+//
+// 包括语言标识标准化、Accept-Language解析与匹配、复数形式选择，
+// 以及供 html/template 使用的复数渲染函数
+package i18n
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultLanguage 系统默认语言
+const DefaultLanguage = "zh-CN"
+
+// SupportedLanguages 系统支持的语言，API翻译和邮件模板按此列表匹配用户语言
+var SupportedLanguages = []string{"zh-CN", "en-US", "ja-JP"}
+
+// 复数类别(CLDR)，目前只区分 one 和 other
+const (
+	PluralOne   = "one"
+	PluralOther = "other"
+)
+
+// languageAliases 常见语言写法到标准标识的映射
+var languageAliases = map[string]string{
+	"zh":       "zh-CN",
+	"zh-cn":    "zh-CN",
+	"zh-hans":  "zh-CN",
+	"chinese":  "zh-CN",
+	"en":       "en-US",
+	"en-us":    "en-US",
+	"english":  "en-US",
+	"ja":       "ja-JP",
+	"ja-jp":    "ja-JP",
+	"japanese": "ja-JP",
+}
+
+// Normalize 标准化语言标识，如 zh、zh_cn 转为 zh-CN，未知语言保持 xx-YY 大小写格式
+func Normalize(lang string) string {
+	lang = strings.TrimSpace(strings.ReplaceAll(lang, "_", "-"))
+	if lang == "" {
+		return ""
+	}
+	if alias, ok := languageAliases[strings.ToLower(lang)]; ok {
+		return alias
+	}
+
+	parts := strings.Split(lang, "-")
+	parts[0] = strings.ToLower(parts[0])
+	for i := 1; i < len(parts); i++ {
+		if len(parts[i]) == 2 {
+			parts[i] = strings.ToUpper(parts[i])
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+// Base 返回语言的基础部分，如 en-GB 返回 en
+func Base(lang string) string {
+	if idx := strings.Index(lang, "-"); idx != -1 {
+		lang = lang[:idx]
+	}
+	return strings.ToLower(lang)
+}
+
+// ParseAcceptLanguage 解析Accept-Language头，按权重从高到低返回标准化后的语言
+//
+// 权重相同时保持原顺序，q=0 和 * 被忽略
+func ParseAcceptLanguage(header string) []string {
+	type weighted struct {
+		lang string
+		q    float64
+	}
+
+	var items []weighted
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		lang := strings.TrimSpace(fields[0])
+		if lang == "" || lang == "*" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if !strings.HasPrefix(param, "q=") {
+				continue
+			}
+			if value, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64); err == nil {
+				q = value
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		items = append(items, weighted{lang: Normalize(lang), q: q})
+	}
+
+	sort.SliceStable(items, func(i, j int) bool { return items[i].q > items[j].q })
+
+	languages := make([]string, 0, len(items))
+	for _, item := range items {
+		languages = append(languages, item.lang)
+	}
+	return languages
+}
+
+// Match 从用户偏好的语言中选出第一个受支持的语言，没有时返回空字符串
+//
+// 每个偏好语言先精确匹配，再按基础语言匹配(如 en-GB 匹配 en-US)
+func Match(preferred, supported []string) string {
+	for _, lang := range preferred {
+		lang = Normalize(lang)
+		if lang == "" {
+			continue
+		}
+		for _, candidate := range supported {
+			if candidate == lang {
+				return candidate
+			}
+		}
+		for _, candidate := range supported {
+			if Base(candidate) == Base(lang) {
+				return candidate
+			}
+		}
+	}
+	return ""
+}
+
+// PluralCategory 返回数量n在语言lang中的复数类别
+//
+// 中文、日文、韩文没有复数变化，总是 other；法语 0 和 1 为 one；其他语言只有 1 为 one
+func PluralCategory(lang string, n float64) string {
+	switch Base(lang) {
+	case "zh", "ja", "ko":
+		return PluralOther
+	case "fr":
+		if n >= 0 && n < 2 {
+			return PluralOne
+		}
+		return PluralOther
+	default:
+		if n == 1 {
+			return PluralOne
+		}
+		return PluralOther
+	}
+}
+
+// Plural 按复数类别在单数和复数形式中选择
+func Plural(lang string, n float64, one, other string) string {
+	if PluralCategory(lang, n) == PluralOne {
+		return one
+	}
+	return other
+}
+
+// FuncMap 返回语言lang的模板函数，可传给 html/template 或 text/template 的 Funcs
+//
+//   - plural: {{plural .expires_in "minute" "minutes"}} 按数量选择单复数形式
+//   - count: {{count .expires_in "minute" "minutes"}} 输出数量及对应形式，如 "1 minute"、"10 minutes"
+func FuncMap(lang string) map[string]interface{} {
+	return map[string]interface{}{
+		"plural": func(n interface{}, one, other string) string {
+			return Plural(lang, toFloat(n), one, other)
+		},
+		"count": func(n interface{}, one, other string) string {
+			value := toFloat(n)
+			return strconv.FormatFloat(value, 'f', -1, 64) + " " + Plural(lang, value, one, other)
+		},
+	}
+}
+
+// toFloat 将模板变量中的数量转为float64，无法识别时返回0
+func toFloat(n interface{}) float64 {
+	switch v := n.(type) {
+	case int:
+		return float64(v)
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case uint:
+		return float64(v)
+	case uint32:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	case float64:
+		return v
+	case string:
+		value, _ := strconv.ParseFloat(v, 64)
+		return value
+	default:
+		value, _ := strconv.ParseFloat(fmt.Sprint(v), 64)
+		return value
+	}
+}
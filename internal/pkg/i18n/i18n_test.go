@@ -0,0 +1,59 @@
+package i18n
+
+import (
+	"bytes"
+	"html/template"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalize(t *testing.T) {
+	assert.Equal(t, "zh-CN", Normalize("zh"))
+	assert.Equal(t, "zh-CN", Normalize("zh_cn"))
+	assert.Equal(t, "en-US", Normalize("EN"))
+	assert.Equal(t, "en-GB", Normalize("en-gb"))
+	assert.Equal(t, "fr-FR", Normalize("fr-FR"))
+	assert.Equal(t, "", Normalize("  "))
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	assert.Equal(t, []string{"ja-JP", "en-US", "fr"}, ParseAcceptLanguage("fr;q=0.5, ja, en;q=0.8, de;q=0, *"))
+	assert.Empty(t, ParseAcceptLanguage(""))
+}
+
+func TestMatch(t *testing.T) {
+	supported := []string{"zh-CN", "en-US", "ja-JP"}
+
+	assert.Equal(t, "ja-JP", Match([]string{"ja-JP", "en-US"}, supported))
+	assert.Equal(t, "en-US", Match([]string{"fr-FR", "en-GB"}, supported), "按基础语言匹配")
+	assert.Equal(t, "zh-CN", Match([]string{"zh-TW"}, supported))
+	assert.Equal(t, "", Match([]string{"fr"}, supported))
+	assert.Equal(t, "", Match(nil, supported))
+}
+
+func TestPlural(t *testing.T) {
+	assert.Equal(t, PluralOne, PluralCategory("en-US", 1))
+	assert.Equal(t, PluralOther, PluralCategory("en-US", 0))
+	assert.Equal(t, PluralOther, PluralCategory("en-US", 1.5))
+	assert.Equal(t, PluralOne, PluralCategory("fr", 0))
+	assert.Equal(t, PluralOther, PluralCategory("zh-CN", 1))
+
+	assert.Equal(t, "minute", Plural("en-US", 1, "minute", "minutes"))
+	assert.Equal(t, "minutes", Plural("en-US", 10, "minute", "minutes"))
+}
+
+func TestFuncMap(t *testing.T) {
+	tmpl, err := template.New("t").Funcs(FuncMap("en-US")).
+		Parse(`{{count .n "minute" "minutes"}}, {{plural .files "file" "files"}}`)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, tmpl.Execute(&buf, map[string]interface{}{"n": 1.0, "files": 3}))
+	assert.Equal(t, "1 minute, files", buf.String())
+
+	buf.Reset()
+	require.NoError(t, tmpl.Execute(&buf, map[string]interface{}{"n": 10.0, "files": uint(1)}))
+	assert.Equal(t, "10 minutes, file", buf.String())
+}
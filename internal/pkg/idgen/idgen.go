@@ -0,0 +1,87 @@
+// Package idgen 可注入的标识符生成器
+//
+// 服务通过构造函数接收 IDGenerator，生产环境使用全局配置的生成器(见 models.SetIDGenerator)，
+// 测试使用 Sequence 生成可预测的标识符，便于断言上传任务ID、分享码等结果。
+//
+// 使用示例：
+//
+//	ids := idgen.NewSequence("upload")
+//	service := file.NewChunkedUploadService(fileRepo, chunkRepo, quota, store, nil, nil, options, nil, ids, logger)
+//	// 第一个上传任务的ID为 upload-000001
+package idgen
+
+import (
+	"fmt"
+	"sync"
+
+	basemodels "cloudpan/internal/pkg/database/models"
+)
+
+// IDGenerator 标识符生成器
+type IDGenerator interface {
+	// NewID 生成对外标识符(模型的UUID列、上传任务ID等)
+	NewID() string
+	// NewShareCode 生成分享码，分享码是访问凭证，实现必须保证不可猜测
+	NewShareCode() string
+}
+
+// globalGenerator 使用全局配置的生成器
+type globalGenerator struct{}
+
+// NewID 生成对外标识符
+func (globalGenerator) NewID() string {
+	return basemodels.GenerateUUID()
+}
+
+// NewShareCode 生成分享码
+func (globalGenerator) NewShareCode() string {
+	return basemodels.GenerateShareCode()
+}
+
+// Default 返回使用全局配置的生成器，运行期间调用 models.SetIDGenerator 后立即生效
+func Default() IDGenerator {
+	return globalGenerator{}
+}
+
+// OrDefault 返回传入的生成器，为nil时返回 Default，供构造函数处理可选参数
+func OrDefault(generator IDGenerator) IDGenerator {
+	if generator == nil {
+		return Default()
+	}
+	return generator
+}
+
+// Sequence 按序号生成可预测标识符的生成器，仅用于测试，并发安全
+//
+// 标识符为 前缀-六位序号，分享码为 前缀首字符加七位序号，共8个字符，与默认分享码长度相同
+type Sequence struct {
+	mu     sync.Mutex
+	prefix string
+	next   int
+}
+
+// NewSequence 创建从1开始计数的生成器，前缀为空时使用 id
+func NewSequence(prefix string) *Sequence {
+	if prefix == "" {
+		prefix = "id"
+	}
+	return &Sequence{prefix: prefix}
+}
+
+// NewID 生成下一个标识符
+func (s *Sequence) NewID() string {
+	return fmt.Sprintf("%s-%06d", s.prefix, s.increment())
+}
+
+// NewShareCode 生成下一个分享码
+func (s *Sequence) NewShareCode() string {
+	return fmt.Sprintf("%s%07d", s.prefix[:1], s.increment())
+}
+
+// increment 返回下一个序号
+func (s *Sequence) increment() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.next++
+	return s.next
+}
@@ -0,0 +1,35 @@
+package idgen
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	basemodels "cloudpan/internal/pkg/database/models"
+)
+
+func TestOrDefault(t *testing.T) {
+	assert.Equal(t, Default(), OrDefault(nil))
+
+	sequence := NewSequence("t")
+	assert.Same(t, sequence, OrDefault(sequence))
+}
+
+func TestDefault_UsesGlobalGenerator(t *testing.T) {
+	generator, err := basemodels.NewIDGenerator(basemodels.IDGeneratorULID, 0)
+	assert.NoError(t, err)
+	basemodels.SetIDGenerator(generator)
+	defer basemodels.SetIDGenerator(nil)
+
+	assert.Len(t, Default().NewID(), 26, "应使用全局配置的ULID生成器")
+	assert.Len(t, Default().NewShareCode(), 8, "分享码不使用ID生成器")
+}
+
+func TestSequence(t *testing.T) {
+	sequence := NewSequence("upload")
+	assert.Equal(t, "upload-000001", sequence.NewID())
+	assert.Equal(t, "upload-000002", sequence.NewID())
+	assert.Equal(t, "u0000003", sequence.NewShareCode())
+
+	assert.Equal(t, "id-000001", NewSequence("").NewID())
+}
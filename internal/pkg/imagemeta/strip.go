@@ -0,0 +1,335 @@
+// Package imagemeta 提供图片元数据处理，用于在分享下载时去除照片中的位置信息
+//
+// 只移除位置相关的元数据，不重新编码图像数据，输出与原图像素完全一致：
+//   - JPEG: 清空EXIF中的GPS IFD(保留方向、拍摄参数等其他标签)，删除XMP段
+//   - PNG: 清空eXIf块中的GPS IFD并重算CRC，删除XMP文本块
+//
+// 使用示例：
+//
+//	if imagemeta.Supported(mimeType) {
+//		err := imagemeta.StripLocation(dst, src, mimeType)
+//	}
+package imagemeta
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"strings"
+)
+
+// 支持的图片类型
+const (
+	MimeJPEG = "image/jpeg"
+	MimePNG  = "image/png"
+)
+
+// ErrUnsupportedFormat 不支持的图片格式
+var ErrUnsupportedFormat = errors.New("unsupported image format")
+
+// ErrMalformedImage 图片结构损坏，无法安全处理
+var ErrMalformedImage = errors.New("malformed image")
+
+// JPEG段标识
+var (
+	exifHeader        = []byte("Exif\x00\x00")
+	xmpHeader         = []byte("http://ns.adobe.com/xap/1.0/\x00")
+	xmpExtendedHeader = []byte("http://ns.adobe.com/xmp/extension/\x00")
+	pngSignature      = []byte("\x89PNG\r\n\x1a\n")
+)
+
+// xmpKeyword PNG中存放XMP的iTXt关键字
+const xmpKeyword = "XML:com.adobe.xmp"
+
+// TIFF标签
+const (
+	tagGPSInfo = 0x8825
+)
+
+// maxSegmentSize 单个PNG元数据块的最大大小，防止恶意文件耗尽内存
+const maxSegmentSize = 16 * 1024 * 1024
+
+// Supported 检查是否支持去除该类型图片的位置信息
+func Supported(mimeType string) bool {
+	switch normalizeMime(mimeType) {
+	case MimeJPEG, MimePNG:
+		return true
+	default:
+		return false
+	}
+}
+
+// StripLocation 复制图片并去除位置信息
+//
+// 结构损坏的图片返回 ErrMalformedImage，调用方不应将原图作为去除后的结果返回
+func StripLocation(dst io.Writer, src io.Reader, mimeType string) error {
+	switch normalizeMime(mimeType) {
+	case MimeJPEG:
+		return stripJPEG(dst, bufio.NewReader(src))
+	case MimePNG:
+		return stripPNG(dst, bufio.NewReader(src))
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedFormat, mimeType)
+	}
+}
+
+// stripJPEG 逐段复制JPEG，扫描数据开始后原样复制剩余内容
+func stripJPEG(dst io.Writer, src *bufio.Reader) error {
+	var soi [2]byte
+	if _, err := io.ReadFull(src, soi[:]); err != nil || soi[0] != 0xFF || soi[1] != 0xD8 {
+		return fmt.Errorf("%w: missing JPEG SOI marker", ErrMalformedImage)
+	}
+	if _, err := dst.Write(soi[:]); err != nil {
+		return err
+	}
+
+	for {
+		marker, err := readJPEGMarker(src)
+		if err != nil {
+			return err
+		}
+
+		// 无长度字段的标记
+		if marker == 0xD9 || (marker >= 0xD0 && marker <= 0xD7) || marker == 0x01 {
+			if _, err := dst.Write([]byte{0xFF, marker}); err != nil {
+				return err
+			}
+			if marker == 0xD9 {
+				_, err = io.Copy(dst, src)
+				return err
+			}
+			continue
+		}
+
+		var lengthBuf [2]byte
+		if _, err := io.ReadFull(src, lengthBuf[:]); err != nil {
+			return fmt.Errorf("%w: truncated segment", ErrMalformedImage)
+		}
+		length := int(binary.BigEndian.Uint16(lengthBuf[:]))
+		if length < 2 {
+			return fmt.Errorf("%w: invalid segment length", ErrMalformedImage)
+		}
+		payload := make([]byte, length-2)
+		if _, err := io.ReadFull(src, payload); err != nil {
+			return fmt.Errorf("%w: truncated segment", ErrMalformedImage)
+		}
+
+		if marker == 0xE1 {
+			switch {
+			case bytes.HasPrefix(payload, xmpHeader), bytes.HasPrefix(payload, xmpExtendedHeader):
+				// XMP可能包含位置，整段删除
+				continue
+			case bytes.HasPrefix(payload, exifHeader):
+				if err := scrubTIFFLocation(payload[len(exifHeader):]); err != nil {
+					return err
+				}
+			}
+		}
+
+		if _, err := dst.Write([]byte{0xFF, marker}); err != nil {
+			return err
+		}
+		if _, err := dst.Write(lengthBuf[:]); err != nil {
+			return err
+		}
+		if _, err := dst.Write(payload); err != nil {
+			return err
+		}
+
+		if marker == 0xDA {
+			// 扫描数据开始，之后不再有元数据段
+			_, err = io.Copy(dst, src)
+			return err
+		}
+	}
+}
+
+// readJPEGMarker 读取下一个段标记，跳过填充字节
+func readJPEGMarker(src *bufio.Reader) (byte, error) {
+	b, err := src.ReadByte()
+	if err != nil || b != 0xFF {
+		return 0, fmt.Errorf("%w: expected JPEG marker", ErrMalformedImage)
+	}
+	for {
+		b, err = src.ReadByte()
+		if err != nil {
+			return 0, fmt.Errorf("%w: truncated marker", ErrMalformedImage)
+		}
+		if b != 0xFF {
+			return b, nil
+		}
+	}
+}
+
+// stripPNG 逐块复制PNG，大块(如IDAT)流式复制
+func stripPNG(dst io.Writer, src *bufio.Reader) error {
+	signature := make([]byte, len(pngSignature))
+	if _, err := io.ReadFull(src, signature); err != nil || !bytes.Equal(signature, pngSignature) {
+		return fmt.Errorf("%w: missing PNG signature", ErrMalformedImage)
+	}
+	if _, err := dst.Write(signature); err != nil {
+		return err
+	}
+
+	for {
+		var header [8]byte
+		if _, err := io.ReadFull(src, header[:]); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("%w: truncated chunk", ErrMalformedImage)
+		}
+		length := int64(binary.BigEndian.Uint32(header[:4]))
+		chunkType := string(header[4:8])
+
+		switch chunkType {
+		case "eXIf", "iTXt":
+			if length > maxSegmentSize {
+				return fmt.Errorf("%w: metadata chunk too large", ErrMalformedImage)
+			}
+			data := make([]byte, length+4)
+			if _, err := io.ReadFull(src, data); err != nil {
+				return fmt.Errorf("%w: truncated chunk", ErrMalformedImage)
+			}
+			data = data[:length]
+
+			if chunkType == "iTXt" && bytes.HasPrefix(data, []byte(xmpKeyword+"\x00")) {
+				continue
+			}
+			if chunkType == "eXIf" {
+				if err := scrubTIFFLocation(data); err != nil {
+					return err
+				}
+			}
+
+			crc := crc32.NewIEEE()
+			crc.Write(header[4:8])
+			crc.Write(data)
+			var crcBuf [4]byte
+			binary.BigEndian.PutUint32(crcBuf[:], crc.Sum32())
+			for _, part := range [][]byte{header[:], data, crcBuf[:]} {
+				if _, err := dst.Write(part); err != nil {
+					return err
+				}
+			}
+		default:
+			if _, err := dst.Write(header[:]); err != nil {
+				return err
+			}
+			if _, err := io.CopyN(dst, src, length+4); err != nil {
+				return fmt.Errorf("%w: truncated chunk", ErrMalformedImage)
+			}
+		}
+
+		if chunkType == "IEND" {
+			_, err := io.Copy(dst, src)
+			return err
+		}
+	}
+}
+
+// scrubTIFFLocation 原地清空TIFF结构中的GPS IFD
+//
+// GPS IFD的条目和外部数据全部置零并将条目数改为0，IFD0中的GPSInfo指针
+// 保留并指向空IFD，不需要移动其他数据的偏移
+func scrubTIFFLocation(tiff []byte) error {
+	if len(tiff) < 8 {
+		return fmt.Errorf("%w: truncated TIFF header", ErrMalformedImage)
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return fmt.Errorf("%w: invalid TIFF byte order", ErrMalformedImage)
+	}
+
+	ifd0 := int(order.Uint32(tiff[4:8]))
+	gpsOffset, found, err := findIFDEntry(tiff, order, ifd0, tagGPSInfo)
+	if err != nil || !found {
+		return err
+	}
+	return clearIFD(tiff, order, int(gpsOffset))
+}
+
+// findIFDEntry 在IFD中查找LONG类型标签的值
+func findIFDEntry(tiff []byte, order binary.ByteOrder, offset int, tag uint16) (uint32, bool, error) {
+	if offset < 8 || offset+2 > len(tiff) {
+		return 0, false, fmt.Errorf("%w: IFD offset out of range", ErrMalformedImage)
+	}
+	count := int(order.Uint16(tiff[offset:]))
+	if offset+2+count*12 > len(tiff) {
+		return 0, false, fmt.Errorf("%w: IFD entries out of range", ErrMalformedImage)
+	}
+	for i := 0; i < count; i++ {
+		entry := tiff[offset+2+i*12:]
+		if order.Uint16(entry) == tag {
+			return order.Uint32(entry[8:12]), true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+// clearIFD 清空IFD的条目及其引用的外部数据
+func clearIFD(tiff []byte, order binary.ByteOrder, offset int) error {
+	if offset < 8 || offset+2 > len(tiff) {
+		return fmt.Errorf("%w: GPS IFD offset out of range", ErrMalformedImage)
+	}
+	count := int(order.Uint16(tiff[offset:]))
+	end := offset + 2 + count*12
+	if end > len(tiff) {
+		return fmt.Errorf("%w: GPS IFD entries out of range", ErrMalformedImage)
+	}
+
+	for i := 0; i < count; i++ {
+		entry := tiff[offset+2+i*12 : offset+2+(i+1)*12]
+		size := tiffTypeSize(order.Uint16(entry[2:4])) * int64(order.Uint32(entry[4:8]))
+		if size > 4 {
+			valueOffset := int64(order.Uint32(entry[8:12]))
+			if valueOffset >= 0 && valueOffset+size <= int64(len(tiff)) {
+				clear(tiff[valueOffset : valueOffset+size])
+			}
+		}
+	}
+	// 条目数置0，原条目区域置0(同时使下一IFD偏移为0)
+	clear(tiff[offset:end])
+	if end+4 <= len(tiff) {
+		clear(tiff[end : end+4])
+	}
+	return nil
+}
+
+// tiffTypeSize 返回TIFF数据类型的单个值大小
+func tiffTypeSize(typ uint16) int64 {
+	switch typ {
+	case 1, 2, 6, 7: // BYTE, ASCII, SBYTE, UNDEFINED
+		return 1
+	case 3, 8: // SHORT, SSHORT
+		return 2
+	case 4, 9, 11: // LONG, SLONG, FLOAT
+		return 4
+	case 5, 10, 12: // RATIONAL, SRATIONAL, DOUBLE
+		return 8
+	default:
+		return 0
+	}
+}
+
+// normalizeMime 规范化内容类型，去除参数部分
+func normalizeMime(mimeType string) string {
+	if i := strings.IndexByte(mimeType, ';'); i >= 0 {
+		mimeType = mimeType[:i]
+	}
+	mimeType = strings.ToLower(strings.TrimSpace(mimeType))
+	if mimeType == "image/jpg" || mimeType == "image/pjpeg" {
+		return MimeJPEG
+	}
+	return mimeType
+}
@@ -0,0 +1,179 @@
+package imagemeta
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gpsLatitude 测试用纬度值(度/分/秒三个RATIONAL)，用于在输出中搜索残留
+var gpsLatitude = []uint32{39, 1, 54, 1, 2711, 100}
+
+// buildTIFF 构造含方向标签和GPS纬度的小端TIFF结构
+func buildTIFF() []byte {
+	order := binary.LittleEndian
+	buf := make([]byte, 8)
+	copy(buf, "II")
+	order.PutUint16(buf[2:], 42)
+	order.PutUint32(buf[4:], 8)
+
+	// IFD0: Orientation=6, GPSInfo -> 38
+	ifd0 := make([]byte, 2+2*12+4)
+	order.PutUint16(ifd0, 2)
+	order.PutUint16(ifd0[2:], 0x0112)
+	order.PutUint16(ifd0[4:], 3)
+	order.PutUint32(ifd0[6:], 1)
+	order.PutUint16(ifd0[10:], 6)
+	order.PutUint16(ifd0[14:], tagGPSInfo)
+	order.PutUint16(ifd0[16:], 4)
+	order.PutUint32(ifd0[18:], 1)
+	order.PutUint32(ifd0[22:], 38)
+	buf = append(buf, ifd0...)
+
+	// GPS IFD(偏移38): LatitudeRef='N', Latitude -> 68
+	gps := make([]byte, 2+2*12+4)
+	order.PutUint16(gps, 2)
+	order.PutUint16(gps[2:], 0x0001)
+	order.PutUint16(gps[4:], 2)
+	order.PutUint32(gps[6:], 2)
+	copy(gps[10:], "N\x00")
+	order.PutUint16(gps[14:], 0x0002)
+	order.PutUint16(gps[16:], 5)
+	order.PutUint32(gps[18:], 3)
+	order.PutUint32(gps[22:], 68)
+	buf = append(buf, gps...)
+
+	for _, v := range gpsLatitude {
+		buf = order.AppendUint32(buf, v)
+	}
+	return buf
+}
+
+func testImage() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for x := 0; x < 8; x++ {
+		img.Set(x, x, color.RGBA{R: 200, A: 255})
+	}
+	return img
+}
+
+// jpegSegment 构造JPEG段
+func jpegSegment(marker byte, payload []byte) []byte {
+	seg := []byte{0xFF, marker, 0, 0}
+	binary.BigEndian.PutUint16(seg[2:], uint16(len(payload)+2))
+	return append(seg, payload...)
+}
+
+// pngChunk 构造带CRC的PNG块
+func pngChunk(chunkType string, data []byte) []byte {
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	chunk = append(chunk, chunkType...)
+	chunk = append(chunk, data...)
+	return binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(append([]byte(chunkType), data...)))
+}
+
+func latitudeBytes() []byte {
+	var b []byte
+	for _, v := range gpsLatitude {
+		b = binary.LittleEndian.AppendUint32(b, v)
+	}
+	return b
+}
+
+func TestStripLocation_JPEG(t *testing.T) {
+	var encoded bytes.Buffer
+	require.NoError(t, jpeg.Encode(&encoded, testImage(), nil))
+	raw := encoded.Bytes()
+
+	xmp := jpegSegment(0xE1, append([]byte("http://ns.adobe.com/xap/1.0/\x00"), `<x:xmpmeta exif:GPSLatitude="39,1.54N"/>`...))
+
+	var input bytes.Buffer
+	input.Write(raw[:2])
+	input.Write(jpegSegment(0xE1, append([]byte("Exif\x00\x00"), buildTIFF()...)))
+	input.Write(xmp)
+	input.Write(raw[2:])
+	require.True(t, bytes.Contains(input.Bytes(), latitudeBytes()))
+
+	var output bytes.Buffer
+	require.NoError(t, StripLocation(&output, bytes.NewReader(input.Bytes()), "image/jpeg"))
+
+	out := output.Bytes()
+	assert.False(t, bytes.Contains(out, latitudeBytes()), "GPS纬度应被清除")
+	assert.False(t, bytes.Contains(out, []byte("GPSLatitude")), "XMP段应被删除")
+	assert.True(t, bytes.Contains(out, []byte("Exif\x00\x00II")), "EXIF段应保留")
+	assert.Equal(t, input.Len()-len(xmp), len(out), "只删除XMP段，其余字节数不变")
+
+	// 方向标签保留
+	exifStart := bytes.Index(out, []byte("Exif\x00\x00")) + 6
+	orientation, found, err := findIFDEntry(out[exifStart:], binary.LittleEndian, 8, 0x0112)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, uint32(6), orientation&0xFFFF)
+
+	_, err = jpeg.Decode(bytes.NewReader(out))
+	assert.NoError(t, err, "输出必须仍是有效的JPEG")
+}
+
+func TestStripLocation_PNG(t *testing.T) {
+	var encoded bytes.Buffer
+	require.NoError(t, png.Encode(&encoded, testImage()))
+	raw := encoded.Bytes()
+	ihdrEnd := 8 + 8 + 13 + 4
+
+	var input bytes.Buffer
+	input.Write(raw[:ihdrEnd])
+	input.Write(pngChunk("eXIf", buildTIFF()))
+	input.Write(pngChunk("iTXt", []byte(xmpKeyword+"\x00\x00\x00\x00\x00<x:xmpmeta GPSLatitude/>")))
+	input.Write(pngChunk("tEXt", []byte("Comment\x00keep me")))
+	input.Write(raw[ihdrEnd:])
+
+	var output bytes.Buffer
+	require.NoError(t, StripLocation(&output, bytes.NewReader(input.Bytes()), "image/png"))
+
+	out := output.Bytes()
+	assert.False(t, bytes.Contains(out, latitudeBytes()))
+	assert.False(t, bytes.Contains(out, []byte("GPSLatitude")))
+	assert.True(t, bytes.Contains(out, []byte("keep me")), "非位置元数据保留")
+
+	_, err := png.Decode(bytes.NewReader(out))
+	assert.NoError(t, err, "CRC重算后仍是有效的PNG")
+}
+
+func TestStripLocation_NoMetadata(t *testing.T) {
+	var encoded bytes.Buffer
+	require.NoError(t, jpeg.Encode(&encoded, testImage(), nil))
+
+	var output bytes.Buffer
+	require.NoError(t, StripLocation(&output, bytes.NewReader(encoded.Bytes()), "image/jpg"))
+	assert.Equal(t, encoded.Bytes(), output.Bytes(), "没有元数据时输出与输入一致")
+}
+
+func TestStripLocation_Errors(t *testing.T) {
+	var output bytes.Buffer
+	assert.ErrorIs(t, StripLocation(&output, strings.NewReader("GIF89a"), "image/gif"), ErrUnsupportedFormat)
+	assert.ErrorIs(t, StripLocation(&output, strings.NewReader("not a jpeg"), "image/jpeg"), ErrMalformedImage)
+
+	// GPS指针越界
+	tiff := buildTIFF()
+	binary.LittleEndian.PutUint32(tiff[8+2+12+8:], 4096)
+	var input bytes.Buffer
+	input.Write([]byte{0xFF, 0xD8})
+	input.Write(jpegSegment(0xE1, append([]byte("Exif\x00\x00"), tiff...)))
+	assert.ErrorIs(t, StripLocation(&output, &input, "image/jpeg"), ErrMalformedImage)
+}
+
+func TestSupported(t *testing.T) {
+	assert.True(t, Supported("image/jpeg"))
+	assert.True(t, Supported("IMAGE/PNG; charset=binary"))
+	assert.False(t, Supported("image/heic"))
+	assert.False(t, Supported("application/pdf"))
+}
@@ -0,0 +1,139 @@
+package indexing
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// 分发器相关错误
+var (
+	// ErrQueueFull 分发队列已满
+	ErrQueueFull = errors.New("indexing queue full")
+	// ErrDispatcherClosed 分发器已关闭
+	ErrDispatcherClosed = errors.New("indexing dispatcher closed")
+)
+
+// DefaultQueueSize 默认分发队列长度
+const DefaultQueueSize = 1024
+
+// Handler 索引文档消费者
+type Handler func(ctx context.Context, doc Document) error
+
+// Dispatcher 进程内异步文档分发器
+//
+// 发布不会阻塞数据库操作：文档进入有界队列后由后台协程依次分发给各消费者，
+// 队列满时返回 ErrQueueFull(调用方记录日志，由全量重建兜底)。
+//
+// 使用示例：
+//
+//	dispatcher := indexing.NewDispatcher(indexing.DefaultQueueSize, logger)
+//	dispatcher.Subscribe("search", searchIndexer.Handle)
+//	indexing.SetPublisher(dispatcher)
+//	go dispatcher.Run(ctx)
+//	defer dispatcher.Close()
+type Dispatcher struct {
+	queue  chan Document
+	logger *zap.Logger
+
+	mu       sync.RWMutex
+	handlers map[string]Handler
+	closed   bool
+	done     chan struct{}
+}
+
+// NewDispatcher 创建文档分发器
+func NewDispatcher(queueSize int, logger *zap.Logger) *Dispatcher {
+	if queueSize <= 0 {
+		queueSize = DefaultQueueSize
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &Dispatcher{
+		queue:    make(chan Document, queueSize),
+		logger:   logger,
+		handlers: make(map[string]Handler),
+		done:     make(chan struct{}),
+	}
+}
+
+// Subscribe 注册消费者，同名消费者会被替换
+func (d *Dispatcher) Subscribe(name string, handler Handler) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.handlers[name] = handler
+}
+
+// Publish 将文档加入分发队列
+func (d *Dispatcher) Publish(_ context.Context, docs ...Document) error {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.closed {
+		return ErrDispatcherClosed
+	}
+
+	for _, doc := range docs {
+		select {
+		case d.queue <- doc:
+		default:
+			return ErrQueueFull
+		}
+	}
+	return nil
+}
+
+// Run 持续分发队列中的文档，直到ctx取消或分发器关闭(关闭时会先处理完剩余文档)
+func (d *Dispatcher) Run(ctx context.Context) {
+	defer close(d.done)
+	for {
+		select {
+		case doc, ok := <-d.queue:
+			if !ok {
+				return
+			}
+			d.dispatch(ctx, doc)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Close 停止接收新文档，Run 处理完剩余文档后退出
+func (d *Dispatcher) Close() {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return
+	}
+	d.closed = true
+	close(d.queue)
+	d.mu.Unlock()
+}
+
+// Done 返回在 Run 退出后关闭的通道
+func (d *Dispatcher) Done() <-chan struct{} {
+	return d.done
+}
+
+// dispatch 将文档交给所有消费者，单个消费者失败不影响其他消费者
+func (d *Dispatcher) dispatch(ctx context.Context, doc Document) {
+	d.mu.RLock()
+	handlers := make(map[string]Handler, len(d.handlers))
+	for name, handler := range d.handlers {
+		handlers[name] = handler
+	}
+	d.mu.RUnlock()
+
+	for name, handler := range handlers {
+		if err := handler(ctx, doc); err != nil {
+			d.logger.Warn("Index document handler failed",
+				zap.String("handler", name),
+				zap.String("document", doc.Key()),
+				zap.String("operation", string(doc.Operation)),
+				zap.Error(err))
+		}
+	}
+}
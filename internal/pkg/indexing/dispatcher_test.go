@@ -0,0 +1,49 @@
+package indexing
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDispatcher_FanOut(t *testing.T) {
+	d := NewDispatcher(8, nil)
+
+	var mu sync.Mutex
+	received := make(map[string][]string)
+	record := func(name string) Handler {
+		return func(_ context.Context, doc Document) error {
+			mu.Lock()
+			defer mu.Unlock()
+			received[name] = append(received[name], doc.Key())
+			return nil
+		}
+	}
+	d.Subscribe("search", record("search"))
+	d.Subscribe("cache", record("cache"))
+	d.Subscribe("broken", func(context.Context, Document) error { return errors.New("boom") })
+
+	go d.Run(context.Background())
+
+	require.NoError(t, d.Publish(context.Background(),
+		NewDeleteDocument(DocumentTypeFile, 1, 1),
+		NewDeleteDocument(DocumentTypeUser, 2, 1)))
+	d.Close()
+	<-d.Done()
+
+	assert.Equal(t, []string{"file:1", "user:2"}, received["search"])
+	assert.Equal(t, []string{"file:1", "user:2"}, received["cache"])
+
+	assert.ErrorIs(t, d.Publish(context.Background(), NewDeleteDocument(DocumentTypeFile, 3, 1)), ErrDispatcherClosed)
+}
+
+func TestDispatcher_QueueFull(t *testing.T) {
+	d := NewDispatcher(1, nil)
+
+	require.NoError(t, d.Publish(context.Background(), NewDeleteDocument(DocumentTypeFile, 1, 1)))
+	assert.ErrorIs(t, d.Publish(context.Background(), NewDeleteDocument(DocumentTypeFile, 2, 1)), ErrQueueFull)
+}
@@ -0,0 +1,56 @@
+package indexing
+
+import (
+	"strconv"
+	"time"
+)
+
+// Operation 索引文档操作类型
+type Operation string
+
+// 索引文档操作类型常量
+const (
+	OperationUpsert Operation = "upsert" // 新增或覆盖文档
+	OperationDelete Operation = "delete" // 删除文档
+)
+
+// 索引文档类型常量
+const (
+	DocumentTypeFile = "file" // 文件
+	DocumentTypeUser = "user" // 用户
+)
+
+// Document 标准化的索引文档
+//
+// 由模型钩子根据数据库中的最新状态生成，供搜索索引、缓存等下游消费者使用。
+// Version 为记录的乐观锁版本号，消费者应丢弃版本低于已处理版本的文档
+type Document struct {
+	Type       string                 `json:"type"`             // 文档类型
+	ID         uint                   `json:"id"`               // 记录主键
+	Operation  Operation              `json:"operation"`        // 操作类型
+	Version    int64                  `json:"version"`          // 记录版本号
+	Fields     map[string]interface{} `json:"fields,omitempty"` // 文档字段(删除操作为空)
+	OccurredAt time.Time              `json:"occurred_at"`      // 变更时间
+}
+
+// Key 文档唯一键，格式为 类型:主键
+func (d Document) Key() string {
+	return d.Type + ":" + strconv.FormatUint(uint64(d.ID), 10)
+}
+
+// Indexable 可被索引的模型
+type Indexable interface {
+	// IndexDocument 生成模型当前状态的索引文档(upsert)
+	IndexDocument() Document
+}
+
+// NewDeleteDocument 创建删除文档
+func NewDeleteDocument(docType string, id uint, version int64) Document {
+	return Document{
+		Type:       docType,
+		ID:         id,
+		Operation:  OperationDelete,
+		Version:    version,
+		OccurredAt: time.Now(),
+	}
+}
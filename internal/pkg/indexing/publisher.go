@@ -0,0 +1,149 @@
+package indexing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"gorm.io/gorm"
+)
+
+// skipSettingKey 会话级别跳过索引的设置键
+const skipSettingKey = "indexing:skip"
+
+// stagedSettingKey 当前语句暂存文档的设置键
+const stagedSettingKey = "indexing:documents"
+
+// Publisher 索引文档发布者
+type Publisher interface {
+	Publish(ctx context.Context, docs ...Document) error
+}
+
+var (
+	publisherMu sync.RWMutex
+	publisher   Publisher
+	suspended   int32
+)
+
+// SetPublisher 设置全局发布者，传入nil表示不发布
+func SetPublisher(p Publisher) {
+	publisherMu.Lock()
+	defer publisherMu.Unlock()
+	publisher = p
+}
+
+// GetPublisher 获取全局发布者，未设置时返回nil
+func GetPublisher() Publisher {
+	publisherMu.RLock()
+	defer publisherMu.RUnlock()
+	return publisher
+}
+
+// Suspend 全局暂停索引发布，返回恢复函数
+//
+// 用于批量迁移、数据修复等场景，避免逐条触发重新加载和发布；
+// 支持嵌套调用，全部恢复后才重新启用。结束后应对受影响的数据执行一次全量重建
+//
+//	resume := indexing.Suspend()
+//	defer resume()
+func Suspend() (resume func()) {
+	atomic.AddInt32(&suspended, 1)
+	var once sync.Once
+	return func() {
+		once.Do(func() { atomic.AddInt32(&suspended, -1) })
+	}
+}
+
+// IsSuspended 索引发布是否已全局暂停
+func IsSuspended() bool {
+	return atomic.LoadInt32(&suspended) > 0
+}
+
+// SkipIndexing 返回跳过索引发布的会话，仅影响通过该会话执行的操作
+//
+//	indexing.SkipIndexing(db).Where("user_id = ?", id).Updates(...)
+func SkipIndexing(db *gorm.DB) *gorm.DB {
+	return db.Set(skipSettingKey, true)
+}
+
+// Enabled 判断当前会话是否需要生成索引文档
+func Enabled(tx *gorm.DB) bool {
+	if IsSuspended() || GetPublisher() == nil {
+		return false
+	}
+	if skip, ok := tx.Get(skipSettingKey); ok {
+		if b, ok := skip.(bool); ok && b {
+			return false
+		}
+	}
+	return true
+}
+
+// StageUpsert 在模型钩子中暂存记录的最新文档
+//
+// 在当前事务内重新读取记录，保证按字段更新时也能生成完整文档；
+// 记录已被(软)删除时暂存删除文档。model 为对应模型的零值指针
+func StageUpsert(tx *gorm.DB, id uint, model Indexable) error {
+	if id == 0 || !Enabled(tx) {
+		return nil
+	}
+
+	if err := tx.Session(&gorm.Session{NewDB: true}).First(model, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			Stage(tx, NewDeleteDocument(model.IndexDocument().Type, id, 0))
+			return nil
+		}
+		return fmt.Errorf("重新加载索引记录失败: %w", err)
+	}
+
+	Stage(tx, model.IndexDocument())
+	return nil
+}
+
+// StageDelete 在模型钩子中暂存删除文档
+func StageDelete(tx *gorm.DB, docType string, id uint, version int64) {
+	if id == 0 || !Enabled(tx) {
+		return
+	}
+	Stage(tx, NewDeleteDocument(docType, id, version))
+}
+
+// Stage 暂存文档，待语句成功提交后由 Flush 发布
+func Stage(tx *gorm.DB, docs ...Document) {
+	key := stagedKey(tx.Statement)
+	var staged []Document
+	if value, ok := tx.Statement.Settings.Load(key); ok {
+		staged, _ = value.([]Document)
+	}
+	tx.Statement.Settings.Store(key, append(staged, docs...))
+}
+
+// Flush 发布当前语句暂存的文档，语句执行失败时丢弃
+//
+// 由数据库插件注册在事务提交回调之后调用
+func Flush(db *gorm.DB) error {
+	key := stagedKey(db.Statement)
+	value, ok := db.Statement.Settings.LoadAndDelete(key)
+	if !ok || db.Error != nil {
+		return nil
+	}
+
+	docs, _ := value.([]Document)
+	p := GetPublisher()
+	if len(docs) == 0 || p == nil {
+		return nil
+	}
+
+	ctx := db.Statement.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return p.Publish(ctx, docs...)
+}
+
+// stagedKey 语句级别的暂存键(与 gorm InstanceSet 的键格式一致)
+func stagedKey(stmt *gorm.Statement) string {
+	return fmt.Sprintf("%p", stmt) + stagedSettingKey
+}
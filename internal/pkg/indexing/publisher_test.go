@@ -0,0 +1,164 @@
+package indexing
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	_ "modernc.org/sqlite" // 使用纯Go的SQLite驱动
+)
+
+// recordingPublisher 记录发布文档的测试发布者
+type recordingPublisher struct {
+	mu   sync.Mutex
+	docs []Document
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, docs ...Document) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.docs = append(p.docs, docs...)
+	return nil
+}
+
+func (p *recordingPublisher) take() []Document {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	docs := p.docs
+	p.docs = nil
+	return docs
+}
+
+// indexedNote 索引测试模型
+type indexedNote struct {
+	ID        uint `gorm:"primarykey"`
+	Title     string
+	Body      string
+	Version   int64          `gorm:"default:1"`
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+func (n *indexedNote) AfterCreate(tx *gorm.DB) error {
+	return StageUpsert(tx, n.ID, &indexedNote{})
+}
+
+func (n *indexedNote) AfterUpdate(tx *gorm.DB) error {
+	return StageUpsert(tx, n.ID, &indexedNote{})
+}
+
+func (n *indexedNote) AfterDelete(tx *gorm.DB) error {
+	StageDelete(tx, "note", n.ID, n.Version)
+	return nil
+}
+
+func (n *indexedNote) IndexDocument() Document {
+	return Document{
+		Type:      "note",
+		ID:        n.ID,
+		Operation: OperationUpsert,
+		Version:   n.Version,
+		Fields:    map[string]interface{}{"title": n.Title, "body": n.Body},
+	}
+}
+
+func setupIndexingTestDB(t *testing.T) (*gorm.DB, *recordingPublisher) {
+	t.Helper()
+	sqlDB, err := sql.Open("sqlite", ":memory:")
+	require.NoError(t, err)
+	db, err := gorm.Open(sqlite.Dialector{Conn: sqlDB}, &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&indexedNote{}))
+
+	// 与数据库插件相同的注册方式
+	flush := func(db *gorm.DB) { _ = Flush(db) }
+	const after = "gorm:commit_or_rollback_transaction"
+	require.NoError(t, db.Callback().Create().After(after).Register("indexing:flush_create", flush))
+	require.NoError(t, db.Callback().Update().After(after).Register("indexing:flush_update", flush))
+	require.NoError(t, db.Callback().Delete().After(after).Register("indexing:flush_delete", flush))
+
+	publisher := &recordingPublisher{}
+	SetPublisher(publisher)
+	t.Cleanup(func() { SetPublisher(nil) })
+	return db, publisher
+}
+
+func TestHooks_PublishAfterCommit(t *testing.T) {
+	db, publisher := setupIndexingTestDB(t)
+
+	note := &indexedNote{Title: "draft", Body: "hello"}
+	require.NoError(t, db.Create(note).Error)
+
+	docs := publisher.take()
+	require.Len(t, docs, 1)
+	assert.Equal(t, OperationUpsert, docs[0].Operation)
+	assert.Equal(t, "note:1", docs[0].Key())
+	assert.Equal(t, "draft", docs[0].Fields["title"])
+
+	// 按字段更新也发布完整文档
+	require.NoError(t, db.Model(&indexedNote{ID: note.ID}).Update("title", "final").Error)
+	docs = publisher.take()
+	require.Len(t, docs, 1)
+	assert.Equal(t, "final", docs[0].Fields["title"])
+	assert.Equal(t, "hello", docs[0].Fields["body"])
+
+	require.NoError(t, db.Delete(note).Error)
+	docs = publisher.take()
+	require.Len(t, docs, 1)
+	assert.Equal(t, OperationDelete, docs[0].Operation)
+	assert.Equal(t, note.ID, docs[0].ID)
+}
+
+func TestHooks_UpdateOfDeletedRecordPublishesDelete(t *testing.T) {
+	db, publisher := setupIndexingTestDB(t)
+
+	note := &indexedNote{Title: "gone"}
+	require.NoError(t, db.Create(note).Error)
+	require.NoError(t, db.Delete(note).Error)
+	publisher.take()
+
+	require.NoError(t, db.Unscoped().Model(note).Update("title", "still gone").Error)
+	docs := publisher.take()
+	require.Len(t, docs, 1)
+	assert.Equal(t, OperationDelete, docs[0].Operation)
+}
+
+func TestHooks_EscapeHatches(t *testing.T) {
+	db, publisher := setupIndexingTestDB(t)
+
+	require.NoError(t, SkipIndexing(db).Create(&indexedNote{Title: "skipped"}).Error)
+	assert.Empty(t, publisher.take())
+
+	resume := Suspend()
+	nested := Suspend()
+	require.NoError(t, db.Create(&indexedNote{Title: "bulk"}).Error)
+	nested()
+	require.NoError(t, db.Create(&indexedNote{Title: "still bulk"}).Error)
+	assert.Empty(t, publisher.take())
+	resume()
+	resume() // 重复调用无副作用
+	assert.False(t, IsSuspended())
+
+	require.NoError(t, db.Create(&indexedNote{Title: "live"}).Error)
+	assert.Len(t, publisher.take(), 1)
+}
+
+func TestFlush_DiscardsOnError(t *testing.T) {
+	db, publisher := setupIndexingTestDB(t)
+
+	tx := db.Session(&gorm.Session{})
+	Stage(tx, NewDeleteDocument("note", 1, 1))
+	_ = tx.AddError(errors.New("statement failed"))
+	require.NoError(t, Flush(tx))
+	assert.Empty(t, publisher.take())
+
+	// 暂存的文档已被清除
+	tx.Error = nil
+	require.NoError(t, Flush(tx))
+	assert.Empty(t, publisher.take())
+}
@@ -0,0 +1,203 @@
+package ldap
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// BER标识符
+const (
+	classUniversal   byte = 0x00
+	classApplication byte = 0x40
+	classContext     byte = 0x80
+	constructed      byte = 0x20
+
+	tagBoolean     byte = 0x01
+	tagInteger     byte = 0x02
+	tagOctetString byte = 0x04
+	tagEnumerated  byte = 0x0a
+	tagSequence         = classUniversal | constructed | 0x10
+	tagSet              = classUniversal | constructed | 0x11
+
+	// maxMessageSize 单个响应消息的大小上限，防止异常的服务器耗尽内存
+	maxMessageSize = 16 << 20
+)
+
+// errMalformed 响应不是有效的BER编码
+var errMalformed = errors.New("ldap: malformed response")
+
+// packet BER编码的数据元素，构造类型的内容为子元素
+type packet struct {
+	tag      byte
+	value    []byte
+	children []*packet
+}
+
+// newConstructed 创建构造类型元素
+func newConstructed(tag byte, children ...*packet) *packet {
+	return &packet{tag: tag | constructed, children: children}
+}
+
+// newString 创建字符串类型元素
+func newString(tag byte, value string) *packet {
+	return &packet{tag: tag, value: []byte(value)}
+}
+
+// newInteger 创建整数类型元素，按最短的补码编码
+func newInteger(tag byte, value int64) *packet {
+	var encoded []byte
+	for {
+		encoded = append([]byte{byte(value)}, encoded...)
+		value >>= 8
+		if (value == 0 && encoded[0]&0x80 == 0) || (value == -1 && encoded[0]&0x80 != 0) {
+			break
+		}
+	}
+	return &packet{tag: tag, value: encoded}
+}
+
+// newBoolean 创建布尔类型元素
+func newBoolean(value bool) *packet {
+	if value {
+		return &packet{tag: tagBoolean, value: []byte{0xff}}
+	}
+	return &packet{tag: tagBoolean, value: []byte{0x00}}
+}
+
+// isConstructed 是否为构造类型
+func (p *packet) isConstructed() bool {
+	return p.tag&constructed != 0
+}
+
+// child 返回第i个子元素，不存在时返回nil
+func (p *packet) child(i int) *packet {
+	if i < 0 || i >= len(p.children) {
+		return nil
+	}
+	return p.children[i]
+}
+
+// int 按补码解析整数内容
+func (p *packet) int() (int64, error) {
+	if len(p.value) == 0 || len(p.value) > 8 {
+		return 0, errMalformed
+	}
+	value := int64(int8(p.value[0]))
+	for _, b := range p.value[1:] {
+		value = value<<8 | int64(b)
+	}
+	return value, nil
+}
+
+// encode 编码为BER字节
+func (p *packet) encode() []byte {
+	content := p.value
+	if p.isConstructed() {
+		content = nil
+		for _, child := range p.children {
+			content = append(content, child.encode()...)
+		}
+	}
+	out := append([]byte{p.tag}, encodeLength(len(content))...)
+	return append(out, content...)
+}
+
+// encodeLength 编码长度，小于128使用短格式
+func encodeLength(length int) []byte {
+	if length < 0x80 {
+		return []byte{byte(length)}
+	}
+	var encoded []byte
+	for length > 0 {
+		encoded = append([]byte{byte(length)}, encoded...)
+		length >>= 8
+	}
+	return append([]byte{0x80 | byte(len(encoded))}, encoded...)
+}
+
+// readPacket 从连接读取一个完整的元素
+func readPacket(r *bufio.Reader) (*packet, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	length, err := readLength(r)
+	if err != nil {
+		return nil, err
+	}
+	if length > maxMessageSize {
+		return nil, fmt.Errorf("ldap: response of %d bytes exceeds limit", length)
+	}
+	content := make([]byte, length)
+	if _, err := io.ReadFull(r, content); err != nil {
+		return nil, err
+	}
+	return parsePacket(tag, content)
+}
+
+// readLength 读取长度，不支持不定长格式
+func readLength(r io.ByteReader) (int, error) {
+	first, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	if first&0x80 == 0 {
+		return int(first), nil
+	}
+	n := int(first & 0x7f)
+	if n == 0 || n > 4 {
+		return 0, errMalformed
+	}
+	length := 0
+	for i := 0; i < n; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		length = length<<8 | int(b)
+	}
+	return length, nil
+}
+
+// parsePacket 解析元素内容，构造类型递归解析子元素
+func parsePacket(tag byte, content []byte) (*packet, error) {
+	p := &packet{tag: tag}
+	if tag&constructed == 0 {
+		p.value = content
+		return p, nil
+	}
+	for len(content) > 0 {
+		if len(content) < 2 {
+			return nil, errMalformed
+		}
+		childTag := content[0]
+		reader := &sliceReader{data: content[1:]}
+		length, err := readLength(reader)
+		if err != nil || length > len(reader.data) {
+			return nil, errMalformed
+		}
+		child, err := parsePacket(childTag, reader.data[:length])
+		if err != nil {
+			return nil, err
+		}
+		p.children = append(p.children, child)
+		content = reader.data[length:]
+	}
+	return p, nil
+}
+
+// sliceReader 从字节切片逐字节读取
+type sliceReader struct {
+	data []byte
+}
+
+func (r *sliceReader) ReadByte() (byte, error) {
+	if len(r.data) == 0 {
+		return 0, errMalformed
+	}
+	b := r.data[0]
+	r.data = r.data[1:]
+	return b, nil
+}
@@ -0,0 +1,197 @@
+package ldap
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// 过滤器的上下文标签(RFC 4511 4.5.1)
+const (
+	filterAnd            = classContext | constructed | 0
+	filterOr             = classContext | constructed | 1
+	filterNot            = classContext | constructed | 2
+	filterEquality       = classContext | constructed | 3
+	filterSubstrings     = classContext | constructed | 4
+	filterGreaterOrEqual = classContext | constructed | 5
+	filterLessOrEqual    = classContext | constructed | 6
+	filterPresent        = classContext | 7
+	filterApprox         = classContext | constructed | 8
+
+	substringInitial = classContext | 0
+	substringAny     = classContext | 1
+	substringFinal   = classContext | 2
+)
+
+// EscapeFilter 转义过滤器中的特殊字符(RFC 4515)，用户输入拼入过滤器前必须转义
+func EscapeFilter(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case '\\', '*', '(', ')', 0:
+			fmt.Fprintf(&b, "\\%02x", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// compileFilter 将字符串形式的过滤器(如 (&(objectClass=person)(uid=alice)))编码为BER
+//
+// 支持与、或、非、相等、存在、子串、大于等于、小于等于和近似匹配，不支持扩展匹配
+func compileFilter(filter string) (*packet, error) {
+	filter = strings.TrimSpace(filter)
+	if !strings.HasPrefix(filter, "(") {
+		filter = "(" + filter + ")"
+	}
+	p, rest, err := parseFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	if rest != "" {
+		return nil, fmt.Errorf("ldap: unexpected %q after filter", rest)
+	}
+	return p, nil
+}
+
+// parseFilter 解析一个带括号的过滤器，返回剩余的字符串
+func parseFilter(s string) (*packet, string, error) {
+	if !strings.HasPrefix(s, "(") {
+		return nil, "", fmt.Errorf("ldap: filter must start with '(' at %q", s)
+	}
+	s = s[1:]
+	if s == "" {
+		return nil, "", fmt.Errorf("ldap: unterminated filter")
+	}
+
+	switch s[0] {
+	case '&', '|':
+		tag := byte(filterAnd)
+		if s[0] == '|' {
+			tag = filterOr
+		}
+		p := newConstructed(tag)
+		s = s[1:]
+		for strings.HasPrefix(s, "(") {
+			child, rest, err := parseFilter(s)
+			if err != nil {
+				return nil, "", err
+			}
+			p.children = append(p.children, child)
+			s = rest
+		}
+		if len(p.children) == 0 {
+			return nil, "", fmt.Errorf("ldap: empty filter list")
+		}
+		return closeFilter(p, s)
+	case '!':
+		child, rest, err := parseFilter(s[1:])
+		if err != nil {
+			return nil, "", err
+		}
+		return closeFilter(newConstructed(filterNot, child), rest)
+	}
+
+	end := strings.IndexByte(s, ')')
+	if end < 0 {
+		return nil, "", fmt.Errorf("ldap: unterminated filter")
+	}
+	p, err := parseItem(s[:end])
+	if err != nil {
+		return nil, "", err
+	}
+	return p, s[end+1:], nil
+}
+
+// closeFilter 检查复合过滤器的右括号
+func closeFilter(p *packet, s string) (*packet, string, error) {
+	if !strings.HasPrefix(s, ")") {
+		return nil, "", fmt.Errorf("ldap: missing ')' in filter")
+	}
+	return p, s[1:], nil
+}
+
+// parseItem 解析不带括号的简单过滤器，如 uid=alice、mail=*、cn=a*b*c
+func parseItem(item string) (*packet, error) {
+	eq := strings.IndexByte(item, '=')
+	if eq <= 0 {
+		return nil, fmt.Errorf("ldap: invalid filter item %q", item)
+	}
+	attr, raw := item[:eq], item[eq+1:]
+	tag := byte(filterEquality)
+	switch attr[len(attr)-1] {
+	case '~':
+		tag, attr = filterApprox, attr[:len(attr)-1]
+	case '>':
+		tag, attr = filterGreaterOrEqual, attr[:len(attr)-1]
+	case '<':
+		tag, attr = filterLessOrEqual, attr[:len(attr)-1]
+	}
+	if attr == "" || strings.ContainsAny(attr, "()*\\ ") {
+		return nil, fmt.Errorf("ldap: invalid attribute in filter item %q", item)
+	}
+
+	if tag == filterEquality && strings.Contains(raw, "*") {
+		if raw == "*" {
+			return newString(filterPresent, attr), nil
+		}
+		return parseSubstrings(attr, raw)
+	}
+	value, err := unescapeFilterValue(raw)
+	if err != nil {
+		return nil, err
+	}
+	return newConstructed(tag, newString(tagOctetString, attr), newString(tagOctetString, value)), nil
+}
+
+// parseSubstrings 解析子串匹配，*分隔开头、中间和结尾部分
+func parseSubstrings(attr, raw string) (*packet, error) {
+	parts := strings.Split(raw, "*")
+	substrings := newConstructed(tagSequence)
+	for i, part := range parts {
+		if part == "" {
+			continue
+		}
+		value, err := unescapeFilterValue(part)
+		if err != nil {
+			return nil, err
+		}
+		tag := byte(substringAny)
+		switch i {
+		case 0:
+			tag = substringInitial
+		case len(parts) - 1:
+			tag = substringFinal
+		}
+		substrings.children = append(substrings.children, newString(tag, value))
+	}
+	return newConstructed(filterSubstrings, newString(tagOctetString, attr), substrings), nil
+}
+
+// unescapeFilterValue 还原 \XX 形式的转义字符
+func unescapeFilterValue(raw string) (string, error) {
+	if strings.ContainsAny(raw, "()") {
+		return "", fmt.Errorf("ldap: unescaped parenthesis in filter value %q", raw)
+	}
+	if !strings.Contains(raw, "\\") {
+		return raw, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(raw); i++ {
+		if raw[i] != '\\' {
+			b.WriteByte(raw[i])
+			continue
+		}
+		if i+3 > len(raw) {
+			return "", fmt.Errorf("ldap: invalid escape in filter value %q", raw)
+		}
+		decoded, err := hex.DecodeString(raw[i+1 : i+3])
+		if err != nil {
+			return "", fmt.Errorf("ldap: invalid escape in filter value %q", raw)
+		}
+		b.Write(decoded)
+		i += 2
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,54 @@
+package ldap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEscapeFilter(t *testing.T) {
+	assert.Equal(t, "alice", EscapeFilter("alice"))
+	assert.Equal(t, `\2a\29\28uid=\5c\00`, EscapeFilter("*)(uid=\\\x00"))
+
+	// 转义后的值按原文匹配
+	p, err := compileFilter("(uid=" + EscapeFilter("a*)(b") + ")")
+	require.NoError(t, err)
+	assert.Equal(t, byte(filterEquality), p.tag)
+	assert.Equal(t, "a*)(b", string(p.child(1).value))
+}
+
+func TestCompileFilter(t *testing.T) {
+	p, err := compileFilter("(&(objectClass=person)(|(uid=alice)(mail=alice@example.com))(!(status=disabled)))")
+	require.NoError(t, err)
+	assert.Equal(t, byte(filterAnd), p.tag)
+	require.Len(t, p.children, 3)
+	assert.Equal(t, byte(filterOr), p.child(1).tag)
+	assert.Equal(t, byte(filterNot), p.child(2).tag)
+	assert.Equal(t, "status", string(p.child(2).child(0).child(0).value))
+
+	p, err = compileFilter("mail=*")
+	require.NoError(t, err, "可以省略最外层括号")
+	assert.Equal(t, byte(filterPresent), p.tag)
+	assert.Equal(t, "mail", string(p.value))
+
+	p, err = compileFilter("(cn=Al*ce*Sm\\2aith)")
+	require.NoError(t, err)
+	assert.Equal(t, byte(filterSubstrings), p.tag)
+	parts := p.child(1).children
+	require.Len(t, parts, 3)
+	assert.Equal(t, byte(substringInitial), parts[0].tag)
+	assert.Equal(t, byte(substringAny), parts[1].tag)
+	assert.Equal(t, byte(substringFinal), parts[2].tag)
+	assert.Equal(t, "Sm*ith", string(parts[2].value))
+
+	p, err = compileFilter("(uidNumber>=1000)")
+	require.NoError(t, err)
+	assert.Equal(t, byte(filterGreaterOrEqual), p.tag)
+	assert.Equal(t, "uidNumber", string(p.child(0).value))
+
+	for _, invalid := range []string{"", "()", "(uid=alice", "(&)", "(uid=alice))", "(=alice)", "(uid=a(b)", "(uid=\\4)"} {
+		_, err := compileFilter(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
@@ -0,0 +1,429 @@
+// Package ldap 精简的LDAPv3客户端
+//
+// 只实现目录认证需要的操作：简单绑定、搜索、StartTLS和解绑(RFC 4511)，
+// 请求按顺序同步执行，一个连接同时只处理一个操作
+package ldap
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout 连接和单个操作的默认超时
+const DefaultTimeout = 10 * time.Second
+
+// 搜索范围
+const (
+	ScopeBaseObject   = 0 // 只搜索基准DN本身
+	ScopeSingleLevel  = 1 // 搜索基准DN的直接下级
+	ScopeWholeSubtree = 2 // 搜索基准DN及其全部下级
+)
+
+// 结果码(RFC 4511 附录A)
+const (
+	ResultSuccess            = 0
+	ResultSizeLimitExceeded  = 4
+	ResultNoSuchObject       = 32
+	ResultInvalidCredentials = 49
+	ResultInsufficientAccess = 50
+	ResultUnwillingToPerform = 53
+)
+
+// 协议操作的应用标签(RFC 4511 4.2-4.12)
+const (
+	tagBindRequest           = classApplication | constructed | 0
+	tagBindResponse          = classApplication | constructed | 1
+	tagUnbindRequest         = classApplication | 2
+	tagSearchRequest         = classApplication | constructed | 3
+	tagSearchResultEntry     = classApplication | constructed | 4
+	tagSearchResultDone      = classApplication | constructed | 5
+	tagSearchResultReference = classApplication | constructed | 19
+	tagExtendedRequest       = classApplication | constructed | 23
+	tagExtendedResponse      = classApplication | constructed | 24
+	tagSimpleAuthentication  = classContext | 0
+	tagExtendedRequestName   = classContext | 0
+)
+
+const (
+	protocolVersion   = 3
+	derefAliasesNever = 0
+	startTLSOID       = "1.3.6.1.4.1.1466.20037"
+	schemeLDAP        = "ldap"
+	schemeLDAPS       = "ldaps"
+	defaultPortLDAP   = "389"
+	defaultPortLDAPS  = "636"
+
+	// maxSearchResultReferences 一次搜索最多接受的引用数，超过时按异常响应处理
+	maxSearchResultReferences = 1000
+)
+
+var (
+	// ErrEmptyPassword 密码为空，简单绑定的空密码会被当作匿名绑定而成功，因此直接拒绝
+	ErrEmptyPassword = errors.New("ldap: empty password")
+	// ErrClosed 连接已关闭
+	ErrClosed = errors.New("ldap: connection closed")
+)
+
+// Error 服务器返回的非成功结果
+type Error struct {
+	Code    int    // 结果码
+	Message string // 诊断信息
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("ldap: result code %d", e.Code)
+	}
+	return fmt.Sprintf("ldap: result code %d: %s", e.Code, e.Message)
+}
+
+// IsResultCode 判断错误是否为指定结果码的服务器错误
+func IsResultCode(err error, code int) bool {
+	var ldapErr *Error
+	return errors.As(err, &ldapErr) && ldapErr.Code == code
+}
+
+// Options 连接选项
+type Options struct {
+	URL                string        // 服务器地址，ldap://host:389 或 ldaps://host:636
+	StartTLS           bool          // ldap:// 连接建立后是否升级为TLS
+	InsecureSkipVerify bool          // 是否跳过服务器证书校验，只用于测试环境
+	Timeout            time.Duration // 连接和单个操作的超时，默认 DefaultTimeout
+}
+
+// SearchRequest 搜索请求
+type SearchRequest struct {
+	BaseDN     string   // 搜索基准DN
+	Scope      int      // 搜索范围，默认 ScopeBaseObject
+	Filter     string   // 过滤器，如 (&(objectClass=person)(uid=alice))
+	Attributes []string // 返回的属性，为空时返回全部用户属性
+	SizeLimit  int      // 最多返回的条目数，0表示不限制(受服务器限制)
+}
+
+// Entry 搜索返回的条目
+type Entry struct {
+	DN         string              // 条目DN
+	Attributes map[string][]string // 属性值，属性名按服务器返回的大小写保存
+}
+
+// Values 返回属性的全部值，属性名不区分大小写
+func (e *Entry) Values(name string) []string {
+	if values, ok := e.Attributes[name]; ok {
+		return values
+	}
+	for attr, values := range e.Attributes {
+		if strings.EqualFold(attr, name) {
+			return values
+		}
+	}
+	return nil
+}
+
+// Value 返回属性的第一个值，没有时返回空
+func (e *Entry) Value(name string) string {
+	values := e.Values(name)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// Conn LDAP连接
+//
+// 使用示例：
+//
+//	conn, err := ldap.Dial(ctx, ldap.Options{URL: "ldaps://ldap.example.com"})
+//	defer conn.Close()
+//	err = conn.Bind(ctx, "cn=reader,dc=example,dc=com", password)
+//	entries, err := conn.Search(ctx, &ldap.SearchRequest{BaseDN: "dc=example,dc=com", Scope: ldap.ScopeWholeSubtree, Filter: "(uid=alice)"})
+type Conn struct {
+	mu      sync.Mutex
+	conn    net.Conn
+	reader  *bufio.Reader
+	timeout time.Duration
+	nextID  int64
+	closed  bool
+}
+
+// Dial 连接服务器，ldaps:// 直接建立TLS连接，StartTLS 时在 ldap:// 连接上升级
+func Dial(ctx context.Context, options Options) (*Conn, error) {
+	u, err := url.Parse(options.URL)
+	if err != nil {
+		return nil, fmt.Errorf("ldap: invalid url: %w", err)
+	}
+	scheme := strings.ToLower(u.Scheme)
+	host := u.Hostname()
+	port := u.Port()
+	switch scheme {
+	case schemeLDAP:
+		if port == "" {
+			port = defaultPortLDAP
+		}
+	case schemeLDAPS:
+		if port == "" {
+			port = defaultPortLDAPS
+		}
+	default:
+		return nil, fmt.Errorf("ldap: unsupported url scheme %q", u.Scheme)
+	}
+	if host == "" {
+		return nil, errors.New("ldap: url host is required")
+	}
+	timeout := options.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	tlsConfig := &tls.Config{ServerName: host, InsecureSkipVerify: options.InsecureSkipVerify, MinVersion: tls.VersionTLS12} // #nosec G402 -- 由配置显式开启
+	dialer := &net.Dialer{Timeout: timeout}
+	var netConn net.Conn
+	if scheme == schemeLDAPS {
+		netConn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+	} else {
+		netConn, err = dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ldap: dial %s: %w", host, err)
+	}
+
+	conn := NewConn(netConn, timeout)
+	if scheme == schemeLDAP && options.StartTLS {
+		if err := conn.startTLS(ctx, tlsConfig); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// NewConn 在已建立的连接上创建LDAP连接，timeout 为0时使用 DefaultTimeout
+func NewConn(netConn net.Conn, timeout time.Duration) *Conn {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Conn{conn: netConn, reader: bufio.NewReader(netConn), timeout: timeout}
+}
+
+// Bind 简单绑定，凭据错误时返回结果码为 ResultInvalidCredentials 的 *Error
+func (c *Conn) Bind(ctx context.Context, dn, password string) error {
+	if password == "" {
+		return ErrEmptyPassword
+	}
+	request := newConstructed(tagBindRequest,
+		newInteger(tagInteger, protocolVersion),
+		newString(tagOctetString, dn),
+		newString(tagSimpleAuthentication, password),
+	)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	id, err := c.send(ctx, request)
+	if err != nil {
+		return err
+	}
+	response, err := c.receive(id)
+	if err != nil {
+		return err
+	}
+	if response.tag != tagBindResponse {
+		return errMalformed
+	}
+	return resultError(response)
+}
+
+// Search 搜索条目，基准DN不存在时返回空结果；超过 SizeLimit 时返回已收到的条目和结果码为 ResultSizeLimitExceeded 的错误
+func (c *Conn) Search(ctx context.Context, req *SearchRequest) ([]*Entry, error) {
+	filter, err := compileFilter(req.Filter)
+	if err != nil {
+		return nil, err
+	}
+	attributes := newConstructed(tagSequence)
+	for _, attr := range req.Attributes {
+		attributes.children = append(attributes.children, newString(tagOctetString, attr))
+	}
+	request := newConstructed(tagSearchRequest,
+		newString(tagOctetString, req.BaseDN),
+		newInteger(tagEnumerated, int64(req.Scope)),
+		newInteger(tagEnumerated, derefAliasesNever),
+		newInteger(tagInteger, int64(req.SizeLimit)),
+		newInteger(tagInteger, int64(c.timeout/time.Second)),
+		newBoolean(false),
+		filter,
+		attributes,
+	)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	id, err := c.send(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*Entry
+	references := 0
+	for {
+		response, err := c.receive(id)
+		if err != nil {
+			return nil, err
+		}
+		switch response.tag {
+		case tagSearchResultEntry:
+			entry, err := parseEntry(response)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, entry)
+		case tagSearchResultReference:
+			// 不追踪引用，只防止异常服务器无限返回
+			if references++; references > maxSearchResultReferences {
+				return nil, errMalformed
+			}
+		case tagSearchResultDone:
+			err := resultError(response)
+			if IsResultCode(err, ResultNoSuchObject) {
+				return nil, nil
+			}
+			return entries, err
+		default:
+			return nil, errMalformed
+		}
+	}
+}
+
+// Close 发送解绑请求并关闭连接
+func (c *Conn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	_, _ = c.send(context.Background(), &packet{tag: tagUnbindRequest})
+	return c.conn.Close()
+}
+
+// startTLS 发送StartTLS扩展请求，成功后在同一连接上完成TLS握手
+func (c *Conn) startTLS(ctx context.Context, config *tls.Config) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	id, err := c.send(ctx, newConstructed(tagExtendedRequest, newString(tagExtendedRequestName, startTLSOID)))
+	if err != nil {
+		return err
+	}
+	response, err := c.receive(id)
+	if err != nil {
+		return err
+	}
+	if response.tag != tagExtendedResponse {
+		return errMalformed
+	}
+	if err := resultError(response); err != nil {
+		return fmt.Errorf("ldap: start tls: %w", err)
+	}
+
+	tlsConn := tls.Client(c.conn, config)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return fmt.Errorf("ldap: tls handshake: %w", err)
+	}
+	c.conn = tlsConn
+	c.reader = bufio.NewReader(tlsConn)
+	return nil
+}
+
+// send 发送请求消息并返回消息ID，截止时间取上下文截止时间和操作超时中较早的一个
+func (c *Conn) send(ctx context.Context, op *packet) (int64, error) {
+	if c.closed && op.tag != tagUnbindRequest {
+		return 0, ErrClosed
+	}
+	deadline := time.Now().Add(c.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := c.conn.SetDeadline(deadline); err != nil {
+		return 0, err
+	}
+
+	c.nextID++
+	message := newConstructed(tagSequence, newInteger(tagInteger, c.nextID), op)
+	if _, err := c.conn.Write(message.encode()); err != nil {
+		return 0, fmt.Errorf("ldap: write request: %w", err)
+	}
+	return c.nextID, nil
+}
+
+// receive 读取指定消息ID的响应，返回其中的操作元素
+func (c *Conn) receive(id int64) (*packet, error) {
+	for {
+		message, err := readPacket(c.reader)
+		if err != nil {
+			return nil, fmt.Errorf("ldap: read response: %w", err)
+		}
+		if message.tag != tagSequence || len(message.children) < 2 {
+			return nil, errMalformed
+		}
+		messageID, err := message.children[0].int()
+		if err != nil {
+			return nil, err
+		}
+		// 消息ID为0的是服务器主动通知(如断开连接通知)，其他ID不属于当前请求
+		if messageID == 0 {
+			if op := message.children[1]; op.tag == tagExtendedResponse {
+				if err := resultError(op); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+		if messageID != id {
+			continue
+		}
+		return message.children[1], nil
+	}
+}
+
+// resultError 解析LDAPResult，成功时返回nil
+func resultError(op *packet) error {
+	codePacket := op.child(0)
+	if codePacket == nil {
+		return errMalformed
+	}
+	code, err := codePacket.int()
+	if err != nil {
+		return err
+	}
+	if code == ResultSuccess {
+		return nil
+	}
+	var message string
+	if diagnostic := op.child(2); diagnostic != nil {
+		message = string(diagnostic.value)
+	}
+	return &Error{Code: int(code), Message: message}
+}
+
+// parseEntry 解析搜索结果条目
+func parseEntry(op *packet) (*Entry, error) {
+	dn, attrs := op.child(0), op.child(1)
+	if dn == nil || attrs == nil {
+		return nil, errMalformed
+	}
+	entry := &Entry{DN: string(dn.value), Attributes: make(map[string][]string, len(attrs.children))}
+	for _, attr := range attrs.children {
+		name, values := attr.child(0), attr.child(1)
+		if name == nil || values == nil {
+			return nil, errMalformed
+		}
+		for _, value := range values.children {
+			entry.Attributes[string(name.value)] = append(entry.Attributes[string(name.value)], string(value.value))
+		}
+	}
+	return entry, nil
+}
@@ -0,0 +1,169 @@
+package ldap
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeServer 在管道上模拟目录服务器，按DN校验密码并返回固定的搜索结果
+type fakeServer struct {
+	passwords map[string]string
+	entries   []*Entry
+	done      int // 搜索结束时返回的结果码
+
+	lastSearch *packet
+}
+
+func (s *fakeServer) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		message, err := readPacket(reader)
+		if err != nil {
+			return
+		}
+		id := message.children[0]
+		op := message.children[1]
+		reply := func(op *packet) {
+			_, _ = conn.Write(newConstructed(tagSequence, id, op).encode())
+		}
+
+		switch op.tag {
+		case tagBindRequest:
+			code := int64(ResultInvalidCredentials)
+			if password, ok := s.passwords[string(op.children[1].value)]; ok && password == string(op.children[2].value) {
+				code = ResultSuccess
+			}
+			reply(result(tagBindResponse, code, "bind"))
+		case tagSearchRequest:
+			s.lastSearch = op
+			// 先发送一条不属于本次请求的消息，客户端应当忽略
+			_, _ = conn.Write(newConstructed(tagSequence, newInteger(tagInteger, 99), result(tagSearchResultDone, 0, "")).encode())
+			for _, entry := range s.entries {
+				attrs := newConstructed(tagSequence)
+				for name, values := range entry.Attributes {
+					set := newConstructed(tagSet)
+					for _, value := range values {
+						set.children = append(set.children, newString(tagOctetString, value))
+					}
+					attrs.children = append(attrs.children, newConstructed(tagSequence, newString(tagOctetString, name), set))
+				}
+				reply(newConstructed(tagSearchResultEntry, newString(tagOctetString, entry.DN), attrs))
+			}
+			reply(result(tagSearchResultDone, int64(s.done), ""))
+		case tagUnbindRequest:
+			return
+		}
+	}
+}
+
+func result(tag byte, code int64, message string) *packet {
+	return newConstructed(tag, newInteger(tagEnumerated, code), newString(tagOctetString, ""), newString(tagOctetString, message))
+}
+
+func dialFake(t *testing.T, server *fakeServer) *Conn {
+	client, serverConn := net.Pipe()
+	go server.serve(serverConn)
+	conn := NewConn(client, time.Second)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestBind(t *testing.T) {
+	ctx := context.Background()
+	conn := dialFake(t, &fakeServer{passwords: map[string]string{"uid=alice,dc=example,dc=com": "secret"}})
+
+	require.NoError(t, conn.Bind(ctx, "uid=alice,dc=example,dc=com", "secret"))
+
+	err := conn.Bind(ctx, "uid=alice,dc=example,dc=com", "wrong")
+	assert.True(t, IsResultCode(err, ResultInvalidCredentials))
+	assert.Contains(t, err.Error(), "bind")
+
+	// 空密码会被服务器当作匿名绑定，不发送请求
+	assert.ErrorIs(t, conn.Bind(ctx, "uid=alice,dc=example,dc=com", ""), ErrEmptyPassword)
+}
+
+func TestSearch(t *testing.T) {
+	ctx := context.Background()
+	server := &fakeServer{entries: []*Entry{{
+		DN: "uid=alice,ou=people,dc=example,dc=com",
+		Attributes: map[string][]string{
+			"mail":     {"alice@example.com"},
+			"memberOf": {"cn=admins,ou=groups,dc=example,dc=com", "cn=dev,ou=groups,dc=example,dc=com"},
+		},
+	}}}
+	conn := dialFake(t, server)
+
+	entries, err := conn.Search(ctx, &SearchRequest{
+		BaseDN:     "dc=example,dc=com",
+		Scope:      ScopeWholeSubtree,
+		Filter:     "(&(objectClass=person)(uid=alice))",
+		Attributes: []string{"mail", "memberOf"},
+		SizeLimit:  2,
+	})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "uid=alice,ou=people,dc=example,dc=com", entries[0].DN)
+	assert.Equal(t, "alice@example.com", entries[0].Value("MAIL"), "属性名不区分大小写")
+	assert.Len(t, entries[0].Values("memberof"), 2)
+	assert.Empty(t, entries[0].Value("cn"))
+
+	// 请求中的基准DN、范围和过滤器
+	request := server.lastSearch
+	assert.Equal(t, "dc=example,dc=com", string(request.child(0).value))
+	scope, _ := request.child(1).int()
+	assert.Equal(t, int64(ScopeWholeSubtree), scope)
+	assert.Equal(t, byte(filterAnd), request.child(6).tag)
+	assert.Len(t, request.child(7).children, 2)
+
+	server.done = ResultNoSuchObject
+	entries, err = conn.Search(ctx, &SearchRequest{BaseDN: "ou=missing,dc=example,dc=com", Filter: "(uid=alice)"})
+	require.NoError(t, err, "基准DN不存在时返回空结果")
+	assert.Empty(t, entries)
+
+	_, err = conn.Search(ctx, &SearchRequest{BaseDN: "dc=example,dc=com", Filter: "(uid=alice"})
+	assert.Error(t, err)
+}
+
+func TestClosedConn(t *testing.T) {
+	conn := dialFake(t, &fakeServer{})
+	require.NoError(t, conn.Close())
+	require.NoError(t, conn.Close())
+	assert.ErrorIs(t, conn.Bind(context.Background(), "uid=alice", "secret"), ErrClosed)
+}
+
+func TestDial_InvalidURL(t *testing.T) {
+	_, err := Dial(context.Background(), Options{URL: "http://ldap.example.com"})
+	assert.Error(t, err)
+	_, err = Dial(context.Background(), Options{URL: "ldap://"})
+	assert.Error(t, err)
+}
+
+func TestPacketEncoding(t *testing.T) {
+	for _, value := range []int64{0, 1, 127, 128, 255, 256, -1, -128, -129, 1 << 40} {
+		p := newInteger(tagInteger, value)
+		decoded, err := parsePacket(p.tag, p.value)
+		require.NoError(t, err)
+		got, err := decoded.int()
+		require.NoError(t, err)
+		assert.Equal(t, value, got)
+	}
+
+	// 长格式长度
+	long := newString(tagOctetString, string(make([]byte, 300)))
+	encoded := long.encode()
+	assert.Equal(t, []byte{tagOctetString, 0x82, 0x01, 0x2c}, encoded[:4])
+	parsed, err := readPacket(bufio.NewReader(bytes.NewReader(encoded)))
+	require.NoError(t, err)
+	assert.Len(t, parsed.value, 300)
+
+	_, err = parsePacket(tagSequence, []byte{tagOctetString, 0x05, 'a'})
+	assert.Error(t, err, "子元素长度超出内容")
+}
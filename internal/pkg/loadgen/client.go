@@ -0,0 +1,110 @@
+package loadgen
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// apiResponse 服务端统一响应结构(与 utils.Response 一致)
+type apiResponse struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// Client 压测HTTP客户端，每个虚拟用户持有独立的令牌
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// NewClient 创建压测客户端
+func NewClient(baseURL, token string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		token:      token,
+		httpClient: httpClient,
+	}
+}
+
+// Login 使用账号密码登录并保存访问令牌
+func (c *Client) Login(ctx context.Context, identifier, password string) error {
+	var result struct {
+		AccessToken string `json:"access_token"`
+	}
+	body := map[string]string{"identifier": identifier, "password": password}
+	if err := c.doJSON(ctx, http.MethodPost, "/api/v1/auth/login", body, &result); err != nil {
+		return fmt.Errorf("登录失败: %w", err)
+	}
+	if result.AccessToken == "" {
+		return fmt.Errorf("登录失败: 响应中缺少访问令牌")
+	}
+	c.token = result.AccessToken
+	return nil
+}
+
+// doJSON 发送JSON请求并解析响应数据
+func (c *Client) doJSON(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	contentType := ""
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("序列化请求失败: %w", err)
+		}
+		reader = bytes.NewReader(data)
+		contentType = "application/json"
+	}
+	return c.do(ctx, method, path, reader, contentType, out)
+}
+
+// do 发送请求，HTTP状态码或业务状态码非成功时返回错误
+func (c *Client) do(ctx context.Context, method, path string, body io.Reader, contentType string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("读取响应失败: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s %s: HTTP %d", method, path, resp.StatusCode)
+	}
+
+	var envelope apiResponse
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return fmt.Errorf("%s %s: 解析响应失败: %w", method, path, err)
+	}
+	if envelope.Code != http.StatusOK {
+		return fmt.Errorf("%s %s: code=%d message=%s", method, path, envelope.Code, envelope.Message)
+	}
+	if out != nil && len(envelope.Data) > 0 {
+		if err := json.Unmarshal(envelope.Data, out); err != nil {
+			return fmt.Errorf("%s %s: 解析响应数据失败: %w", method, path, err)
+		}
+	}
+	return nil
+}
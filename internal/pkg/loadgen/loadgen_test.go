@@ -0,0 +1,155 @@
+package loadgen
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPercentile(t *testing.T) {
+	samples := make([]time.Duration, 100)
+	for i := range samples {
+		samples[i] = time.Duration(i+1) * time.Millisecond
+	}
+
+	assert.Equal(t, 50*time.Millisecond, Percentile(samples, 50))
+	assert.Equal(t, 99*time.Millisecond, Percentile(samples, 99))
+	assert.Equal(t, 100*time.Millisecond, Percentile(samples, 100))
+	assert.Equal(t, time.Millisecond, Percentile(samples, 0))
+	assert.Equal(t, time.Duration(0), Percentile(nil, 50))
+}
+
+func TestRecorderSummaries(t *testing.T) {
+	recorder := NewRecorder()
+	recorder.Record("list", 3*time.Millisecond, nil)
+	recorder.Record("list", time.Millisecond, nil)
+	recorder.Record("list", 2*time.Millisecond, nil)
+	recorder.Record("search", 0, errors.New("boom"))
+
+	summaries := recorder.Summaries()
+	require.Len(t, summaries, 2)
+	assert.Equal(t, Summary{
+		Operation: "list", Count: 3,
+		Min: time.Millisecond, Mean: 2 * time.Millisecond, Max: 3 * time.Millisecond,
+		P50: 2 * time.Millisecond, P90: 3 * time.Millisecond, P95: 3 * time.Millisecond, P99: 3 * time.Millisecond,
+	}, summaries[0])
+	assert.Equal(t, Summary{Operation: "search", Errors: 1}, summaries[1])
+
+	var buf bytes.Buffer
+	report := &Report{Elapsed: time.Second, Users: 1, Summaries: summaries}
+	require.NoError(t, report.WriteText(&buf))
+	assert.Contains(t, buf.String(), "p99")
+	assert.Contains(t, buf.String(), "search")
+}
+
+func TestParseMix(t *testing.T) {
+	mix, err := ParseMix("upload=1, list=5,search=0")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"upload": 1, "list": 5, "search": 0}, mix)
+
+	_, err = ParseMix("download=1")
+	assert.Error(t, err)
+	_, err = ParseMix("list")
+	assert.Error(t, err)
+	_, err = ParseMix("list=-1")
+	assert.Error(t, err)
+}
+
+// newFakeServer 模拟上传、列表和搜索接口
+func newFakeServer(t *testing.T, chunks *int64) *httptest.Server {
+	t.Helper()
+	respond := func(w http.ResponseWriter, data interface{}) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"code": 200, "message": "ok", "data": data})
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/auth/login", func(w http.ResponseWriter, r *http.Request) {
+		respond(w, map[string]string{"access_token": "token"})
+	})
+	mux.HandleFunc("/api/v1/files/uploads", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		respond(w, map[string]string{"upload_id": "u1"})
+	})
+	mux.HandleFunc("/api/v1/files/uploads/u1/", func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "/chunks/") {
+			atomic.AddInt64(chunks, 1)
+		}
+		respond(w, nil)
+	})
+	mux.HandleFunc("/api/v1/files", func(w http.ResponseWriter, r *http.Request) {
+		respond(w, []string{})
+	})
+	mux.HandleFunc("/api/v1/files/search", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestRunner(t *testing.T) {
+	var chunks int64
+	server := newFakeServer(t, &chunks)
+	defer server.Close()
+
+	runner, err := NewRunner(Config{
+		Target:     server.URL,
+		Users:      3,
+		Iterations: 4,
+		Identifier: "bench@example.com",
+		Password:   "secret",
+		Mix:        map[string]int{ScenarioUpload: 1, ScenarioList: 1, ScenarioSearch: 1},
+		ChunkSize:  16,
+		Chunks:     2,
+	}, server.Client())
+	require.NoError(t, err)
+
+	report, err := runner.Run(context.Background())
+	require.NoError(t, err)
+
+	counts := make(map[string]Summary)
+	total := 0
+	for _, s := range report.Summaries {
+		counts[s.Operation] = s
+	}
+	for _, name := range []string{ScenarioUpload, ScenarioList, ScenarioSearch} {
+		total += counts[name].Count + counts[name].Errors
+	}
+	assert.Equal(t, 12, total)
+	assert.Equal(t, 3, counts["login"].Count)
+	assert.Equal(t, 0, counts[ScenarioSearch].Count)
+	assert.Equal(t, int64(counts[ScenarioUpload].Count*2), atomic.LoadInt64(&chunks))
+	assert.Equal(t, counts[ScenarioUpload].Count*2, counts["upload.chunk"].Count)
+}
+
+func TestRunnerLoginFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"code": 1010, "message": "密码错误"})
+	}))
+	defer server.Close()
+
+	runner, err := NewRunner(Config{Target: server.URL, Users: 2, Iterations: 1, Identifier: "a", Password: "b"}, server.Client())
+	require.NoError(t, err)
+
+	_, err = runner.Run(context.Background())
+	assert.Error(t, err)
+}
+
+func TestConfigValidate(t *testing.T) {
+	_, err := NewRunner(Config{Users: 1, Iterations: 1, Token: "t"}, nil)
+	assert.Error(t, err)
+	_, err = NewRunner(Config{Target: "http://x", Users: 1, Token: "t"}, nil)
+	assert.Error(t, err)
+	_, err = NewRunner(Config{Target: "http://x", Users: 1, Iterations: 1}, nil)
+	assert.Error(t, err)
+	_, err = NewRunner(Config{Target: "http://x", Users: 1, Iterations: 1, Token: "t", Mix: map[string]int{ScenarioList: 0}}, nil)
+	assert.Error(t, err)
+}
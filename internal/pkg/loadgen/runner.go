@@ -0,0 +1,328 @@
+package loadgen
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	mathrand "math/rand"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 压测场景
+const (
+	ScenarioUpload = "upload" // 分片上传：初始化、逐片上传、完成合并
+	ScenarioList   = "list"   // 文件夹列表
+	ScenarioSearch = "search" // 文件搜索
+)
+
+// Config 压测配置
+type Config struct {
+	Target     string         // 目标环境地址，如 http://localhost:8080
+	Users      int            // 并发虚拟用户数
+	Duration   time.Duration  // 压测持续时间
+	Iterations int            // 每个用户的最大迭代次数，0表示不限制
+	RampUp     time.Duration  // 在该时间内逐步启动全部用户
+	Mix        map[string]int // 场景权重
+	Token      string         // 访问令牌，设置后不再登录
+	Identifier string         // 登录账号
+	Password   string         // 登录密码
+	ChunkSize  int            // 上传分片大小(字节)
+	Chunks     int            // 每个文件的分片数
+	ParentID   uint           // 上传和列表使用的文件夹ID，0表示根目录
+	Queries    []string       // 搜索关键词
+	Timeout    time.Duration  // 单个请求超时时间
+}
+
+// DefaultMix 默认场景权重，列表请求占多数
+var DefaultMix = map[string]int{ScenarioUpload: 1, ScenarioList: 5, ScenarioSearch: 2}
+
+// ParseMix 解析场景权重，格式为 "upload=1,list=5,search=2"
+func ParseMix(value string) (map[string]int, error) {
+	mix := make(map[string]int)
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, weightText, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("场景权重格式错误: %s", part)
+		}
+		name = strings.TrimSpace(name)
+		if !isKnownScenario(name) {
+			return nil, fmt.Errorf("未知场景: %s", name)
+		}
+		weight, err := strconv.Atoi(strings.TrimSpace(weightText))
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("场景权重必须为非负整数: %s", part)
+		}
+		mix[name] = weight
+	}
+	return mix, nil
+}
+
+// isKnownScenario 判断是否为支持的场景
+func isKnownScenario(name string) bool {
+	return name == ScenarioUpload || name == ScenarioList || name == ScenarioSearch
+}
+
+// validate 检查配置并填充默认值
+func (c *Config) validate() error {
+	if c.Target == "" {
+		return fmt.Errorf("目标地址不能为空")
+	}
+	if c.Users <= 0 {
+		return fmt.Errorf("并发用户数必须大于0")
+	}
+	if c.Duration <= 0 && c.Iterations <= 0 {
+		return fmt.Errorf("必须设置压测时间或迭代次数")
+	}
+	if c.Token == "" && c.Identifier == "" {
+		return fmt.Errorf("必须提供访问令牌或登录账号")
+	}
+	if len(c.Mix) == 0 {
+		c.Mix = DefaultMix
+	}
+	total := 0
+	for _, weight := range c.Mix {
+		total += weight
+	}
+	if total == 0 {
+		return fmt.Errorf("场景权重之和必须大于0")
+	}
+	if c.ChunkSize <= 0 {
+		c.ChunkSize = 1024 * 1024
+	}
+	if c.Chunks <= 0 {
+		c.Chunks = 4
+	}
+	if len(c.Queries) == 0 {
+		c.Queries = []string{"report", "photo", "backup"}
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = 30 * time.Second
+	}
+	return nil
+}
+
+// Runner 压测执行器
+type Runner struct {
+	config     Config
+	httpClient *http.Client
+	recorder   *Recorder
+	chunk      []byte
+}
+
+// NewRunner 创建压测执行器
+func NewRunner(config Config, httpClient *http.Client) (*Runner, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	if httpClient == nil {
+		httpClient = &http.Client{
+			Timeout: config.Timeout,
+			Transport: &http.Transport{
+				MaxIdleConns:        config.Users * 2,
+				MaxIdleConnsPerHost: config.Users * 2,
+				IdleConnTimeout:     90 * time.Second,
+			},
+		}
+	}
+
+	// 所有用户共用同一份随机分片数据，避免压测端成为瓶颈
+	chunk := make([]byte, config.ChunkSize)
+	if _, err := rand.Read(chunk); err != nil {
+		return nil, fmt.Errorf("生成分片数据失败: %w", err)
+	}
+
+	return &Runner{
+		config:     config,
+		httpClient: httpClient,
+		recorder:   NewRecorder(),
+		chunk:      chunk,
+	}, nil
+}
+
+// Run 执行压测并返回报告，ctx取消或达到持续时间/迭代次数后结束
+func (r *Runner) Run(ctx context.Context) (*Report, error) {
+	if r.config.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.config.Duration)
+		defer cancel()
+	}
+
+	started := time.Now()
+	var wg sync.WaitGroup
+	errCh := make(chan error, r.config.Users)
+	for i := 0; i < r.config.Users; i++ {
+		delay := time.Duration(0)
+		if r.config.RampUp > 0 {
+			delay = r.config.RampUp * time.Duration(i) / time.Duration(r.config.Users)
+		}
+		wg.Add(1)
+		go func(user int, delay time.Duration) {
+			defer wg.Done()
+			if err := r.runUser(ctx, user, delay); err != nil {
+				errCh <- err
+			}
+		}(i, delay)
+	}
+	wg.Wait()
+	close(errCh)
+
+	report := &Report{
+		Elapsed:   time.Since(started),
+		Users:     r.config.Users,
+		Summaries: r.recorder.Summaries(),
+	}
+
+	// 全部用户都无法开始时视为压测失败
+	var errs []error
+	for err := range errCh {
+		errs = append(errs, err)
+	}
+	if len(errs) == r.config.Users {
+		return report, fmt.Errorf("所有虚拟用户启动失败: %w", errs[0])
+	}
+	return report, nil
+}
+
+// runUser 执行单个虚拟用户的压测循环
+func (r *Runner) runUser(ctx context.Context, user int, delay time.Duration) error {
+	if delay > 0 {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+	}
+
+	client := NewClient(r.config.Target, r.config.Token, r.httpClient)
+	if r.config.Token == "" {
+		started := time.Now()
+		err := client.Login(ctx, r.config.Identifier, r.config.Password)
+		r.recorder.Record("login", time.Since(started), err)
+		if err != nil {
+			return err
+		}
+	}
+
+	rng := mathrand.New(mathrand.NewSource(time.Now().UnixNano() + int64(user)))
+	scenarios := r.weightedScenarios()
+	for i := 0; r.config.Iterations <= 0 || i < r.config.Iterations; i++ {
+		if ctx.Err() != nil {
+			return nil
+		}
+		scenario := scenarios[rng.Intn(len(scenarios))]
+		started := time.Now()
+		err := r.runScenario(ctx, client, scenario, user, i, rng)
+		// 压测结束时被取消的请求不计入统计
+		if err != nil && ctx.Err() != nil {
+			return nil
+		}
+		r.recorder.Record(scenario, time.Since(started), err)
+	}
+	return nil
+}
+
+// weightedScenarios 按权重展开场景列表，顺序固定便于复现
+func (r *Runner) weightedScenarios() []string {
+	names := make([]string, 0, len(r.config.Mix))
+	for name := range r.config.Mix {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var scenarios []string
+	for _, name := range names {
+		for i := 0; i < r.config.Mix[name]; i++ {
+			scenarios = append(scenarios, name)
+		}
+	}
+	return scenarios
+}
+
+// runScenario 执行一次场景
+func (r *Runner) runScenario(ctx context.Context, client *Client, scenario string, user, iteration int, rng *mathrand.Rand) error {
+	switch scenario {
+	case ScenarioUpload:
+		return r.upload(ctx, client, fmt.Sprintf("loadgen-%d-%d-%d.bin", time.Now().Unix(), user, iteration))
+	case ScenarioList:
+		return r.list(ctx, client)
+	case ScenarioSearch:
+		return r.search(ctx, client, r.config.Queries[rng.Intn(len(r.config.Queries))])
+	}
+	return fmt.Errorf("未知场景: %s", scenario)
+}
+
+// upload 执行分片上传，每个步骤单独记录延迟
+func (r *Runner) upload(ctx context.Context, client *Client, filename string) error {
+	var session struct {
+		UploadID string `json:"upload_id"`
+	}
+	body := map[string]interface{}{
+		"filename":   filename,
+		"size":       int64(r.config.ChunkSize) * int64(r.config.Chunks),
+		"chunk_size": r.config.ChunkSize,
+	}
+	if r.config.ParentID > 0 {
+		body["parent_id"] = r.config.ParentID
+	}
+
+	err := r.timed(ctx, "upload.init", func() error {
+		return client.doJSON(ctx, http.MethodPost, "/api/v1/files/uploads", body, &session)
+	})
+	if err != nil {
+		return err
+	}
+	if session.UploadID == "" {
+		return fmt.Errorf("初始化上传响应中缺少upload_id")
+	}
+
+	base := "/api/v1/files/uploads/" + url.PathEscape(session.UploadID)
+	for i := 0; i < r.config.Chunks; i++ {
+		path := fmt.Sprintf("%s/chunks/%d", base, i)
+		err := r.timed(ctx, "upload.chunk", func() error {
+			return client.do(ctx, http.MethodPut, path, bytes.NewReader(r.chunk), "application/octet-stream", nil)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return r.timed(ctx, "upload.complete", func() error {
+		return client.doJSON(ctx, http.MethodPost, base+"/complete", nil, nil)
+	})
+}
+
+// list 获取文件夹列表
+func (r *Runner) list(ctx context.Context, client *Client) error {
+	query := url.Values{"page": {"1"}, "page_size": {"50"}}
+	if r.config.ParentID > 0 {
+		query.Set("parent_id", strconv.FormatUint(uint64(r.config.ParentID), 10))
+	}
+	return client.doJSON(ctx, http.MethodGet, "/api/v1/files?"+query.Encode(), nil, nil)
+}
+
+// search 搜索文件
+func (r *Runner) search(ctx context.Context, client *Client, keyword string) error {
+	query := url.Values{"q": {keyword}, "page": {"1"}, "page_size": {"20"}}
+	return client.doJSON(ctx, http.MethodGet, "/api/v1/files/search?"+query.Encode(), nil, nil)
+}
+
+// timed 执行操作并记录耗时，压测结束时被取消的请求不计入统计
+func (r *Runner) timed(ctx context.Context, operation string, fn func() error) error {
+	started := time.Now()
+	err := fn()
+	if err == nil || ctx.Err() == nil {
+		r.recorder.Record(operation, time.Since(started), err)
+	}
+	return err
+}
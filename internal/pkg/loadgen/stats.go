@@ -0,0 +1,141 @@
+package loadgen
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// Recorder 线程安全的延迟记录器，按操作名称分组
+type Recorder struct {
+	mu      sync.Mutex
+	samples map[string][]time.Duration
+	errors  map[string]int
+}
+
+// NewRecorder 创建延迟记录器
+func NewRecorder() *Recorder {
+	return &Recorder{
+		samples: make(map[string][]time.Duration),
+		errors:  make(map[string]int),
+	}
+}
+
+// Record 记录一次操作的耗时和结果
+func (r *Recorder) Record(operation string, latency time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		r.errors[operation]++
+		return
+	}
+	r.samples[operation] = append(r.samples[operation], latency)
+}
+
+// Summary 单个操作的统计结果，延迟只统计成功的请求
+type Summary struct {
+	Operation string        `json:"operation"`
+	Count     int           `json:"count"`
+	Errors    int           `json:"errors"`
+	Min       time.Duration `json:"min"`
+	Mean      time.Duration `json:"mean"`
+	P50       time.Duration `json:"p50"`
+	P90       time.Duration `json:"p90"`
+	P95       time.Duration `json:"p95"`
+	P99       time.Duration `json:"p99"`
+	Max       time.Duration `json:"max"`
+}
+
+// Report 压测报告
+type Report struct {
+	Elapsed   time.Duration `json:"elapsed"`
+	Users     int           `json:"users"`
+	Summaries []Summary     `json:"summaries"`
+}
+
+// Summaries 计算各操作的统计结果，按操作名称排序
+func (r *Recorder) Summaries() []Summary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	names := make(map[string]struct{}, len(r.samples)+len(r.errors))
+	for name := range r.samples {
+		names[name] = struct{}{}
+	}
+	for name := range r.errors {
+		names[name] = struct{}{}
+	}
+
+	summaries := make([]Summary, 0, len(names))
+	for name := range names {
+		summaries = append(summaries, summarize(name, r.samples[name], r.errors[name]))
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Operation < summaries[j].Operation })
+	return summaries
+}
+
+// summarize 计算单个操作的统计结果
+func summarize(operation string, samples []time.Duration, errors int) Summary {
+	summary := Summary{Operation: operation, Count: len(samples), Errors: errors}
+	if len(samples) == 0 {
+		return summary
+	}
+
+	sorted := make([]time.Duration, len(samples))
+	copy(sorted, samples)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var total time.Duration
+	for _, sample := range sorted {
+		total += sample
+	}
+	summary.Min = sorted[0]
+	summary.Max = sorted[len(sorted)-1]
+	summary.Mean = total / time.Duration(len(sorted))
+	summary.P50 = Percentile(sorted, 50)
+	summary.P90 = Percentile(sorted, 90)
+	summary.P95 = Percentile(sorted, 95)
+	summary.P99 = Percentile(sorted, 99)
+	return summary
+}
+
+// Percentile 计算已排序样本的百分位数(最近秩法)
+func Percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(p/100*float64(len(sorted)) + 0.999999)
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return sorted[rank-1]
+}
+
+// WriteText 以表格形式输出报告
+func (r *Report) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "users: %d\telapsed: %s\t\n\n", r.Users, r.Elapsed.Round(time.Millisecond))
+	fmt.Fprintln(tw, "operation\tcount\terrors\trps\tmin\tmean\tp50\tp90\tp95\tp99\tmax\t")
+	for _, s := range r.Summaries {
+		rps := 0.0
+		if r.Elapsed > 0 {
+			rps = float64(s.Count) / r.Elapsed.Seconds()
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.1f\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t\n",
+			s.Operation, s.Count, s.Errors, rps,
+			formatLatency(s.Min), formatLatency(s.Mean), formatLatency(s.P50), formatLatency(s.P90),
+			formatLatency(s.P95), formatLatency(s.P99), formatLatency(s.Max))
+	}
+	return tw.Flush()
+}
+
+// formatLatency 格式化延迟，保留到0.1毫秒
+func formatLatency(d time.Duration) string {
+	return d.Round(100 * time.Microsecond).String()
+}
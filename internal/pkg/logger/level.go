@@ -0,0 +1,76 @@
+package logger
+
+import (
+	"fmt"
+	"log"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// LevelSwitch 应用日志级别开关
+//
+// InitLogger 创建的Logger通过 zap.AtomicLevel 读取级别，运行时修改立即对所有持有该Logger的处理器和服务生效，
+// 不需要重建Logger；修改只在当前进程内有效，重启后恢复为配置文件中的级别
+type LevelSwitch struct {
+	atomic zap.AtomicLevel
+}
+
+// Levels 全局应用日志的级别开关
+var Levels = NewLevelSwitch()
+
+// NewLevelSwitch 创建级别开关，初始级别为info
+func NewLevelSwitch() *LevelSwitch {
+	return &LevelSwitch{atomic: zap.NewAtomicLevel()}
+}
+
+// settableLevels 允许运行时设置的级别，panic和fatal级别会屏蔽错误日志，不允许通过接口设置
+var settableLevels = []string{"debug", "info", "warn", "error"}
+
+// Level 返回当前日志级别名称
+func (s *LevelSwitch) Level() string {
+	return s.atomic.Level().String()
+}
+
+// SetLevel 修改日志级别，接受 debug、info、warn(warning)、error
+func (s *LevelSwitch) SetLevel(name string) error {
+	level, err := getLogLevel(name)
+	if err != nil {
+		return err
+	}
+	if level > zapcore.ErrorLevel {
+		return fmt.Errorf("unsupported level: %s", name)
+	}
+	s.atomic.SetLevel(level)
+	return nil
+}
+
+// SettableLevels 返回允许运行时设置的级别
+func (s *LevelSwitch) SettableLevels() []string {
+	return append([]string(nil), settableLevels...)
+}
+
+// RedirectStdLog 将标准库log的输出写入应用日志，返回恢复函数
+//
+// 启动流程和第三方库通过标准库log输出的信息与应用日志使用相同的格式、输出和轮转设置，
+// 未初始化应用日志时不做任何修改
+func RedirectStdLog() func() {
+	if Logger == nil {
+		return func() {}
+	}
+	return zap.RedirectStdLog(Logger)
+}
+
+// StdLogger 返回按指定级别写入应用日志的标准库Logger，其 Writer() 供只接受 io.Writer 的库使用(如Gin的调试输出)
+//
+// 未初始化应用日志时返回标准库默认Logger
+func StdLogger(level zapcore.Level) *log.Logger {
+	if Logger == nil {
+		return log.Default()
+	}
+	stdLogger, err := zap.NewStdLogAt(Logger, level)
+	if err != nil {
+		return log.Default()
+	}
+	return stdLogger
+}
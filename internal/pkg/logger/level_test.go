@@ -0,0 +1,47 @@
+package logger
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLevelSwitch(t *testing.T) {
+	levels := NewLevelSwitch()
+	assert.Equal(t, "info", levels.Level())
+
+	require.NoError(t, levels.SetLevel("WARNING"))
+	assert.Equal(t, "warn", levels.Level())
+
+	assert.Error(t, levels.SetLevel("fatal"), "fatal会屏蔽错误日志")
+	assert.Error(t, levels.SetLevel("verbose"))
+	assert.Equal(t, "warn", levels.Level())
+}
+
+// TestInitLogger_RuntimeLevel 运行时修改级别对已创建的Logger立即生效
+func TestInitLogger_RuntimeLevel(t *testing.T) {
+	originalLogger, originalLevel := Logger, Levels.Level()
+	defer func() {
+		Logger = originalLogger
+		require.NoError(t, Levels.SetLevel(originalLevel))
+	}()
+
+	require.NoError(t, InitLogger(LogConfig{Level: "info", Format: "json", Output: "console"}))
+	serviceLogger := Logger.Named("service")
+	assert.False(t, serviceLogger.Core().Enabled(zapcore.DebugLevel))
+
+	require.NoError(t, Levels.SetLevel("debug"))
+	assert.True(t, serviceLogger.Core().Enabled(zapcore.DebugLevel))
+	assert.Equal(t, "debug", Levels.Level())
+}
+
+func TestStdLogger_Uninitialized(t *testing.T) {
+	originalLogger := Logger
+	defer func() { Logger = originalLogger }()
+	Logger = nil
+
+	assert.NotNil(t, StdLogger(zapcore.InfoLevel))
+	RedirectStdLog()()
+}
@@ -174,8 +174,9 @@ func createFileWriter(config LogConfig) *lumberjack.Logger {
 
 // setupLogger 设置Logger
 func setupLogger(encoder zapcore.Encoder, writeSyncer zapcore.WriteSyncer, level zapcore.Level, config LogConfig) error {
-	// 创建核心
-	core := zapcore.NewCore(encoder, writeSyncer, level)
+	// 创建核心，级别由全局级别开关控制，可在运行时修改
+	Levels.atomic.SetLevel(level)
+	core := zapcore.NewCore(encoder, writeSyncer, Levels.atomic)
 
 	// 创建Logger
 	Logger = zap.New(core, zap.AddCaller(), zap.AddStacktrace(zapcore.ErrorLevel))
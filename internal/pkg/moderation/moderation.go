@@ -0,0 +1,130 @@
+// Package moderation 提供图片内容审核，用于公开分享前检查违规内容
+//
+// 审核由外部服务完成(自建的NSFW模型服务或云审核API)，本包只定义扫描接口和通用的HTTP适配器；
+// 接入其他审核API时实现 Scanner 接口即可
+package moderation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"cloudpan/internal/pkg/config"
+)
+
+// DefaultTimeout 单次审核的默认超时
+const DefaultTimeout = 10 * time.Second
+
+// maxResponseSize 审核接口响应体的大小上限
+const maxResponseSize = 64 << 10
+
+// Label 审核标签及置信度
+type Label struct {
+	Name  string  `json:"name"`  // 标签名，如 nsfw、violence
+	Score float64 `json:"score"` // 置信度，0到1之间
+}
+
+// Result 审核结果
+type Result struct {
+	Labels []Label `json:"labels"`
+}
+
+// Top 返回置信度最高的标签，没有标签时返回零值
+func (r *Result) Top() Label {
+	var top Label
+	for _, label := range r.Labels {
+		if label.Score > top.Score {
+			top = label
+		}
+	}
+	return top
+}
+
+// Scanner 图片内容审核接口
+type Scanner interface {
+	// Scan 审核图片内容，mimeType 为图片的MIME类型
+	Scan(ctx context.Context, src io.Reader, mimeType string) (*Result, error)
+}
+
+// HTTPScanner 通过HTTP接口审核图片
+//
+// 图片内容作为请求体POST到 Endpoint，Content-Type 为图片的MIME类型，配置了密钥时以 Bearer 令牌发送；
+// 接口返回 {"labels":[{"name":"nsfw","score":0.97}]} 形式的JSON
+//
+// 使用示例：
+//
+//	scanner, err := NewHTTPScanner("http://127.0.0.1:8090/v1/moderate", "", nil)
+//	result, err := scanner.Scan(ctx, reader, "image/jpeg")
+type HTTPScanner struct {
+	endpoint string
+	apiKey   string
+	client   *http.Client
+}
+
+// NewHTTPScanner 创建HTTP审核适配器，client 可以为nil，此时使用 DefaultTimeout 超时的默认客户端
+func NewHTTPScanner(endpoint, apiKey string, client *http.Client) (*HTTPScanner, error) {
+	if strings.TrimSpace(endpoint) == "" {
+		return nil, errors.New("moderation: endpoint is required")
+	}
+	if client == nil {
+		client = &http.Client{Timeout: DefaultTimeout}
+	}
+	return &HTTPScanner{endpoint: endpoint, apiKey: apiKey, client: client}, nil
+}
+
+// FromConfig 根据配置创建审核适配器，未启用时返回nil
+func FromConfig(cfg config.ModerationConfig) (Scanner, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	scanner, err := NewHTTPScanner(cfg.Endpoint, cfg.APIKey, &http.Client{Timeout: timeout})
+	if err != nil {
+		return nil, err
+	}
+	return scanner, nil
+}
+
+// Scan 审核图片内容
+func (s *HTTPScanner) Scan(ctx context.Context, src io.Reader, mimeType string) (*Result, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, src)
+	if err != nil {
+		return nil, fmt.Errorf("moderation: build request: %w", err)
+	}
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+	req.Header.Set("Content-Type", mimeType)
+	req.Header.Set("Accept", "application/json")
+	if s.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("moderation: request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("moderation: read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("moderation: endpoint returned status %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+	var result Result
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("moderation: decode response: %w", err)
+	}
+	return &result, nil
+}
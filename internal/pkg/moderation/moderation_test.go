@@ -0,0 +1,60 @@
+package moderation
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"cloudpan/internal/pkg/config"
+)
+
+func TestHTTPScanner_Scan(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer key", r.Header.Get("Authorization"))
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		switch string(body) {
+		case "broken":
+			http.Error(w, "model unavailable", http.StatusServiceUnavailable)
+		default:
+			assert.Equal(t, "image/png", r.Header.Get("Content-Type"))
+			w.Write([]byte(`{"labels":[{"name":"violence","score":0.2},{"name":"nsfw","score":0.93}]}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	scanner, err := NewHTTPScanner(server.URL, "key", nil)
+	require.NoError(t, err)
+
+	result, err := scanner.Scan(context.Background(), strings.NewReader("png"), "image/png")
+	require.NoError(t, err)
+	assert.Len(t, result.Labels, 2)
+	assert.Equal(t, Label{Name: "nsfw", Score: 0.93}, result.Top())
+
+	_, err = scanner.Scan(context.Background(), strings.NewReader("broken"), "image/png")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "503")
+}
+
+func TestResult_TopEmpty(t *testing.T) {
+	assert.Equal(t, Label{}, (&Result{}).Top())
+}
+
+func TestFromConfig(t *testing.T) {
+	scanner, err := FromConfig(config.ModerationConfig{})
+	require.NoError(t, err)
+	assert.Nil(t, scanner, "未启用时不审核")
+
+	_, err = FromConfig(config.ModerationConfig{Enabled: true})
+	assert.Error(t, err, "缺少审核接口地址")
+
+	scanner, err = FromConfig(config.ModerationConfig{Enabled: true, Endpoint: "http://127.0.0.1:8090/v1/moderate"})
+	require.NoError(t, err)
+	assert.NotNil(t, scanner)
+}
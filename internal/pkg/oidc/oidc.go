@@ -0,0 +1,229 @@
+// Package oidc 实现OpenID Connect依赖方(relying party)的授权码流程
+//
+// 包括发现文档解析、带PKCE(S256)的授权地址生成、授权码换取令牌和ID令牌验签。
+// 签名公钥从身份提供方的JWKS地址获取并缓存，遇到未知的kid时重新获取。
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultHTTPTimeout 访问身份提供方的默认超时
+const DefaultHTTPTimeout = 10 * time.Second
+
+// maxResponseSize 身份提供方响应体的大小上限
+const maxResponseSize = 1 << 20
+
+// ErrInvalidToken ID令牌验证失败
+var ErrInvalidToken = errors.New("oidc: invalid id token")
+
+// Metadata 发现文档(.well-known/openid-configuration)中使用的字段
+type Metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+}
+
+// Config 依赖方在身份提供方登记的客户端参数
+type Config struct {
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string // 除openid外额外申请的scope
+}
+
+// Token 授权码换取的令牌
+type Token struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	IDToken     string `json:"id_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// Provider 已完成发现的身份提供方
+//
+// 使用示例：
+//
+//	provider, err := oidc.NewProvider(ctx, nil, "https://login.example.com")
+//	url := provider.AuthCodeURL(cfg, state, nonce, verifier)
+//	token, err := provider.Exchange(ctx, cfg, code, verifier)
+//	idToken, err := provider.Verify(ctx, cfg, token.IDToken, nonce)
+type Provider struct {
+	Metadata Metadata
+
+	client *http.Client
+	keys   *keySet
+}
+
+// NewProvider 获取发现文档并创建身份提供方
+//
+// client 可以为nil，此时使用 DefaultHTTPTimeout 超时的默认客户端。
+// 发现文档中的issuer必须与配置一致(忽略末尾的/)
+func NewProvider(ctx context.Context, client *http.Client, issuer string) (*Provider, error) {
+	if client == nil {
+		client = &http.Client{Timeout: DefaultHTTPTimeout}
+	}
+	issuer = strings.TrimSuffix(strings.TrimSpace(issuer), "/")
+	if issuer == "" {
+		return nil, errors.New("oidc: issuer is empty")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: build discovery request: %w", err)
+	}
+	var metadata Metadata
+	if err := doJSON(client, req, &metadata); err != nil {
+		return nil, fmt.Errorf("oidc: discovery: %w", err)
+	}
+	if strings.TrimSuffix(metadata.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("oidc: issuer mismatch: expected %q, got %q", issuer, metadata.Issuer)
+	}
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JWKSURI == "" {
+		return nil, errors.New("oidc: discovery document is missing required endpoints")
+	}
+
+	return &Provider{
+		Metadata: metadata,
+		client:   client,
+		keys:     newKeySet(client, metadata.JWKSURI),
+	}, nil
+}
+
+// AuthCodeURL 生成授权地址
+//
+// state 用于关联回调请求，nonce 写入ID令牌用于防重放，verifier 为PKCE校验码(见 NewVerifier)
+func (p *Provider) AuthCodeURL(cfg Config, state, nonce, verifier string) string {
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", cfg.ClientID)
+	params.Set("redirect_uri", cfg.RedirectURL)
+	params.Set("scope", strings.Join(scopes(cfg.Scopes), " "))
+	params.Set("state", state)
+	params.Set("nonce", nonce)
+	params.Set("code_challenge", CodeChallenge(verifier))
+	params.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(p.Metadata.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return p.Metadata.AuthorizationEndpoint + separator + params.Encode()
+}
+
+// Exchange 用授权码换取令牌
+//
+// 配置了客户端密钥时使用client_secret_basic认证，否则作为公共客户端在表单中提交client_id
+func (p *Provider) Exchange(ctx context.Context, cfg Config, code, verifier string) (*Token, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", cfg.RedirectURL)
+	form.Set("code_verifier", verifier)
+	if cfg.ClientSecret == "" {
+		form.Set("client_id", cfg.ClientID)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.Metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("oidc: build token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(cfg.ClientID), url.QueryEscape(cfg.ClientSecret))
+	}
+
+	var token Token
+	if err := doJSON(p.client, req, &token); err != nil {
+		return nil, fmt.Errorf("oidc: token exchange: %w", err)
+	}
+	if token.IDToken == "" {
+		return nil, errors.New("oidc: token response has no id_token")
+	}
+	return &token, nil
+}
+
+// NewVerifier 生成PKCE校验码(RFC 7636)
+func NewVerifier() (string, error) {
+	return randomString(32)
+}
+
+// NewNonce 生成随机的state或nonce
+func NewNonce() (string, error) {
+	return randomString(24)
+}
+
+// CodeChallenge 计算PKCE校验码的S256摘要
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// scopes 返回包含openid的去重scope列表
+func scopes(extra []string) []string {
+	result := []string{"openid"}
+	for _, scope := range extra {
+		scope = strings.TrimSpace(scope)
+		if scope == "" || contains(result, scope) {
+			continue
+		}
+		result = append(result, scope)
+	}
+	return result
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// errorResponse OAuth 2.0 错误响应
+type errorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// doJSON 发送请求并解析JSON响应，非2xx响应返回身份提供方给出的错误
+func doJSON(client *http.Client, req *http.Request, out interface{}) error {
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return fmt.Errorf("read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var oauthErr errorResponse
+		if json.Unmarshal(body, &oauthErr) == nil && oauthErr.Error != "" {
+			if oauthErr.ErrorDescription != "" {
+				return fmt.Errorf("%s: %s", oauthErr.Error, oauthErr.ErrorDescription)
+			}
+			return errors.New(oauthErr.Error)
+		}
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
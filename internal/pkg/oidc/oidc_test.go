@@ -0,0 +1,205 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testIdP 最小的OIDC身份提供方：发现文档、JWKS和令牌端点
+type testIdP struct {
+	server     *httptest.Server
+	key        *rsa.PrivateKey
+	kid        string
+	claims     jwt.MapClaims
+	jwksCalls  int
+	tokenForms []url.Values
+}
+
+func newTestIdP(t *testing.T) *testIdP {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	idp := &testIdP{key: key, kid: "key-1"}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(Metadata{
+			Issuer:                idp.server.URL,
+			AuthorizationEndpoint: idp.server.URL + "/authorize",
+			TokenEndpoint:         idp.server.URL + "/token",
+			JWKSURI:               idp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		idp.jwksCalls++
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": []jsonWebKey{{
+			Kty: "RSA",
+			Kid: idp.kid,
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(idp.key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(idp.key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		form := r.PostForm
+		if user, pass, ok := r.BasicAuth(); ok {
+			form.Set("basic_user", user)
+			form.Set("basic_pass", pass)
+		}
+		idp.tokenForms = append(idp.tokenForms, form)
+		if form.Get("code") != "good-code" {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(errorResponse{Error: "invalid_grant", ErrorDescription: "code expired"})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(Token{AccessToken: "at", TokenType: "Bearer", IDToken: idp.sign(t, idp.claims)})
+	})
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+func (idp *testIdP) sign(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = idp.kid
+	signed, err := token.SignedString(idp.key)
+	require.NoError(t, err)
+	return signed
+}
+
+func (idp *testIdP) validClaims() jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":            idp.server.URL,
+		"aud":            "client-1",
+		"sub":            "user-42",
+		"email":          "alice@example.com",
+		"email_verified": true,
+		"nonce":          "n-1",
+		"groups":         []string{"engineering", "admins"},
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+	}
+}
+
+func TestProvider_AuthorizationCodeFlow(t *testing.T) {
+	ctx := context.Background()
+	idp := newTestIdP(t)
+	idp.claims = idp.validClaims()
+	cfg := Config{ClientID: "client-1", ClientSecret: "s3cret", RedirectURL: "https://app.example.com/sso/callback", Scopes: []string{"email", "openid", "profile"}}
+
+	provider, err := NewProvider(ctx, nil, idp.server.URL+"/")
+	require.NoError(t, err)
+
+	authURL, err := url.Parse(provider.AuthCodeURL(cfg, "state-1", "n-1", "verifier-1"))
+	require.NoError(t, err)
+	query := authURL.Query()
+	assert.Equal(t, "/authorize", authURL.Path)
+	assert.Equal(t, "openid email profile", query.Get("scope"))
+	assert.Equal(t, "state-1", query.Get("state"))
+	assert.Equal(t, CodeChallenge("verifier-1"), query.Get("code_challenge"))
+	assert.Equal(t, "S256", query.Get("code_challenge_method"))
+
+	token, err := provider.Exchange(ctx, cfg, "good-code", "verifier-1")
+	require.NoError(t, err)
+	form := idp.tokenForms[0]
+	assert.Equal(t, "verifier-1", form.Get("code_verifier"))
+	assert.Equal(t, "client-1", form.Get("basic_user"))
+	assert.Equal(t, "s3cret", form.Get("basic_pass"))
+
+	idToken, err := provider.Verify(ctx, cfg, token.IDToken, "n-1")
+	require.NoError(t, err)
+	assert.Equal(t, "user-42", idToken.Subject)
+	assert.Equal(t, "alice@example.com", idToken.Email)
+	assert.True(t, idToken.EmailVerified)
+	assert.Equal(t, []string{"engineering", "admins"}, idToken.Strings("groups"))
+
+	// 公钥已缓存
+	_, err = provider.Verify(ctx, cfg, token.IDToken, "n-1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, idp.jwksCalls)
+
+	_, err = provider.Exchange(ctx, cfg, "bad-code", "verifier-1")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid_grant")
+}
+
+func TestProvider_VerifyRejects(t *testing.T) {
+	ctx := context.Background()
+	idp := newTestIdP(t)
+	cfg := Config{ClientID: "client-1"}
+	provider, err := NewProvider(ctx, nil, idp.server.URL)
+	require.NoError(t, err)
+
+	cases := map[string]func(jwt.MapClaims){
+		"wrong audience": func(c jwt.MapClaims) { c["aud"] = "other-client" },
+		"wrong issuer":   func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" },
+		"expired":        func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() },
+		"nonce mismatch": func(c jwt.MapClaims) { c["nonce"] = "other" },
+		"missing exp":    func(c jwt.MapClaims) { delete(c, "exp") },
+		"azp mismatch":   func(c jwt.MapClaims) { c["aud"] = []string{"client-1", "other"}; c["azp"] = "other" },
+	}
+	for name, mutate := range cases {
+		t.Run(name, func(t *testing.T) {
+			claims := idp.validClaims()
+			mutate(claims)
+			_, err := provider.Verify(ctx, cfg, idp.sign(t, claims), "n-1")
+			assert.True(t, errors.Is(err, ErrInvalidToken), "got %v", err)
+		})
+	}
+
+	t.Run("unsigned token", func(t *testing.T) {
+		raw, err := jwt.NewWithClaims(jwt.SigningMethodNone, idp.validClaims()).SignedString(jwt.UnsafeAllowNoneSignatureType)
+		require.NoError(t, err)
+		_, err = provider.Verify(ctx, cfg, raw, "n-1")
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("unknown key", func(t *testing.T) {
+		other, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, idp.validClaims())
+		token.Header["kid"] = "rotated"
+		raw, err := token.SignedString(other)
+		require.NoError(t, err)
+		_, err = provider.Verify(ctx, cfg, raw, "n-1")
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+}
+
+func TestNewProvider_IssuerMismatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(Metadata{Issuer: "https://other.example.com", AuthorizationEndpoint: "a", TokenEndpoint: "t", JWKSURI: "j"})
+	}))
+	defer server.Close()
+
+	_, err := NewProvider(context.Background(), nil, server.URL)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "issuer mismatch")
+}
+
+func TestIDToken_Strings(t *testing.T) {
+	token := &IDToken{Claims: map[string]interface{}{
+		"list":   []interface{}{"a", 1, "b"},
+		"spaced": "a b,c",
+	}}
+	assert.Equal(t, []string{"a", "b"}, token.Strings("list"))
+	assert.Equal(t, []string{"a", "b", "c"}, token.Strings("spaced"))
+	assert.Nil(t, token.Strings("missing"))
+}
@@ -0,0 +1,111 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// clockSkew 验证ID令牌时间声明时允许的时钟偏差
+const clockSkew = time.Minute
+
+// signingMethods ID令牌允许的签名算法，不接受none和HMAC
+var signingMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+// IDToken 验证通过的ID令牌
+type IDToken struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Claims        map[string]interface{} // 全部声明，用于读取组等扩展声明
+}
+
+// Verify 验证ID令牌的签名、签发者、受众、有效期和nonce
+func (p *Provider) Verify(ctx context.Context, cfg Config, rawIDToken, nonce string) (*IDToken, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(rawIDToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.keys.key(ctx, kid)
+	},
+		jwt.WithValidMethods(signingMethods),
+		jwt.WithIssuer(p.Metadata.Issuer),
+		jwt.WithAudience(cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(clockSkew),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	if got, _ := claims["nonce"].(string); nonce != "" && got != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidToken)
+	}
+	// 多个受众时授权方(azp)必须是本客户端
+	if aud, _ := claims.GetAudience(); len(aud) > 1 {
+		if azp, _ := claims["azp"].(string); azp != cfg.ClientID {
+			return nil, fmt.Errorf("%w: authorized party mismatch", ErrInvalidToken)
+		}
+	}
+
+	subject, _ := claims.GetSubject()
+	if subject == "" {
+		return nil, fmt.Errorf("%w: missing subject", ErrInvalidToken)
+	}
+	email, _ := claims["email"].(string)
+	name, _ := claims["name"].(string)
+	return &IDToken{
+		Subject:       subject,
+		Email:         strings.TrimSpace(email),
+		EmailVerified: boolClaim(claims["email_verified"]),
+		Name:          name,
+		Claims:        claims,
+	}, nil
+}
+
+// Strings 读取字符串数组声明(如groups)，也接受以空格或逗号分隔的字符串
+func (t *IDToken) Strings(claim string) []string {
+	switch value := t.Claims[claim].(type) {
+	case []interface{}:
+		result := make([]string, 0, len(value))
+		for _, item := range value {
+			if s, ok := item.(string); ok && s != "" {
+				result = append(result, s)
+			}
+		}
+		return result
+	case []string:
+		return value
+	case string:
+		return strings.FieldsFunc(value, func(r rune) bool { return r == ' ' || r == ',' })
+	default:
+		return nil
+	}
+}
+
+// boolClaim 解析布尔声明，部分身份提供方以字符串返回email_verified
+func boolClaim(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case string:
+		return strings.EqualFold(v, "true")
+	default:
+		return false
+	}
+}
+
+// randomString 生成n字节随机数的base64url编码
+func randomString(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("oidc: generate random: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
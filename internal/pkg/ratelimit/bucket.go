@@ -0,0 +1,125 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Bucket 令牌桶限流器
+//
+// 桶容量为 burst，每分钟补充 perMinute 个令牌，每次调用 Take 消耗一个令牌。
+// 与 Limiter 的固定窗口相比，允许短时突发且不会在窗口边界放行双倍请求，用于接口请求限流
+//
+// 使用示例：
+//
+//	bucket := ratelimit.NewMemoryBucket()
+//	result, err := bucket.Take(ctx, cache.Keys.RateLimit(ip, "global"), 60, 100)
+//	if err == nil && !result.Allowed {
+//		c.Header("Retry-After", strconv.Itoa(int(result.RetryAfter.Seconds())))
+//	}
+type Bucket interface {
+	Take(ctx context.Context, key string, perMinute, burst int) (*Result, error)
+}
+
+// memoryTokens 内存令牌桶状态
+type memoryTokens struct {
+	tokens    float64
+	updatedAt time.Time
+	expiresAt time.Time
+}
+
+// MemoryBucket 进程内令牌桶限流器，用于单实例部署或Redis不可用时
+type MemoryBucket struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryTokens
+	now       func() time.Time
+	lastSweep time.Time
+}
+
+// NewMemoryBucket 创建进程内令牌桶限流器
+func NewMemoryBucket() *MemoryBucket {
+	return &MemoryBucket{
+		buckets: make(map[string]*memoryTokens),
+		now:     time.Now,
+	}
+}
+
+// Take 补充令牌后尝试消耗一个令牌，令牌不足时返回需要等待的时间
+func (b *MemoryBucket) Take(_ context.Context, key string, perMinute, burst int) (*Result, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.sweep(now)
+
+	interval := tokenInterval(perMinute)
+	capacity := float64(bucketCapacity(perMinute, burst))
+
+	state, ok := b.buckets[key]
+	if !ok || !now.Before(state.expiresAt) {
+		state = &memoryTokens{tokens: capacity, updatedAt: now}
+		b.buckets[key] = state
+	}
+	if elapsed := now.Sub(state.updatedAt); elapsed > 0 {
+		state.tokens = math.Min(capacity, state.tokens+float64(elapsed)/float64(interval))
+	}
+	state.updatedAt = now
+	// 桶补满后状态与新建时相同，过期即可删除
+	state.expiresAt = now.Add(time.Duration(capacity * float64(interval)))
+
+	result := &Result{}
+	if state.tokens >= 1 {
+		state.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration(math.Ceil((1 - state.tokens) * float64(interval)))
+	}
+	result.Remaining = int(state.tokens)
+	return result, nil
+}
+
+// PruneExpired 立即清理已补满的令牌桶，返回删除的数量，由定期维护任务调用
+func (b *MemoryBucket) PruneExpired() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.prune(b.now())
+}
+
+// sweep 定期清理已补满的令牌桶，避免键无限增长
+func (b *MemoryBucket) sweep(now time.Time) {
+	if now.Sub(b.lastSweep) < sweepInterval {
+		return
+	}
+	b.prune(now)
+}
+
+// prune 删除已补满的令牌桶并返回删除的数量，调用方需持有锁
+func (b *MemoryBucket) prune(now time.Time) int {
+	b.lastSweep = now
+	removed := 0
+	for key, state := range b.buckets {
+		if !now.Before(state.expiresAt) {
+			delete(b.buckets, key)
+			removed++
+		}
+	}
+	return removed
+}
+
+// tokenInterval 补充一个令牌所需的时间
+func tokenInterval(perMinute int) time.Duration {
+	if perMinute < 1 {
+		perMinute = 1
+	}
+	return time.Minute / time.Duration(perMinute)
+}
+
+// bucketCapacity 桶容量，未配置突发容量时等于每分钟令牌数
+func bucketCapacity(perMinute, burst int) int {
+	if burst > 0 {
+		return burst
+	}
+	return max(perMinute, 1)
+}
@@ -0,0 +1,94 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Counter 总量计数器
+//
+// 与 Limiter 的固定窗口不同，计数在有效期内持续累加，达到上限后拒绝，
+// 用于分享最大访问次数、最大下载次数等总量限制。
+// 键不存在时以调用方提供的当前值(通常来自数据库)为初始值，计数过期后重新从数据库同步
+//
+// 使用示例：
+//
+//	counter := ratelimit.NewMemoryCounter()
+//	count, ok, err := counter.Consume(ctx, cache.Keys.ShareCounter("access", "3"), share.AccessCount, *share.MaxAccess, time.Hour)
+//	if err == nil && !ok {
+//		return pkgErrors.ErrQuotaExceeded
+//	}
+type Counter interface {
+	Consume(ctx context.Context, key string, current, limit int, ttl time.Duration) (count int, ok bool, err error)
+}
+
+// memoryCount 内存计数
+type memoryCount struct {
+	count     int
+	expiresAt time.Time
+}
+
+// MemoryCounter 进程内总量计数器，用于单实例部署或Redis不可用时
+type MemoryCounter struct {
+	mu        sync.Mutex
+	counts    map[string]*memoryCount
+	now       func() time.Time
+	lastSweep time.Time
+}
+
+// NewMemoryCounter 创建进程内总量计数器
+func NewMemoryCounter() *MemoryCounter {
+	return &MemoryCounter{
+		counts: make(map[string]*memoryCount),
+		now:    time.Now,
+	}
+}
+
+// Consume 未达到上限时计数加一，返回计数后的值；已达到上限时不计数并返回false
+func (c *MemoryCounter) Consume(_ context.Context, key string, current, limit int, ttl time.Duration) (int, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	c.sweep(now)
+
+	entry, ok := c.counts[key]
+	if !ok || !now.Before(entry.expiresAt) {
+		entry = &memoryCount{count: current, expiresAt: now.Add(ttl)}
+		c.counts[key] = entry
+	}
+	if entry.count >= limit {
+		return entry.count, false, nil
+	}
+	entry.count++
+	return entry.count, true, nil
+}
+
+// PruneExpired 立即清理过期计数，返回删除的数量，由定期维护任务调用
+func (c *MemoryCounter) PruneExpired() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.prune(c.now())
+}
+
+// sweep 定期清理过期计数，避免键无限增长
+func (c *MemoryCounter) sweep(now time.Time) {
+	if now.Sub(c.lastSweep) < sweepInterval {
+		return
+	}
+	c.prune(now)
+}
+
+// prune 删除过期计数并返回删除的数量，调用方需持有锁
+func (c *MemoryCounter) prune(now time.Time) int {
+	c.lastSweep = now
+	removed := 0
+	for key, entry := range c.counts {
+		if !now.Before(entry.expiresAt) {
+			delete(c.counts, key)
+			removed++
+		}
+	}
+	return removed
+}
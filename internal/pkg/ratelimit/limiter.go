@@ -0,0 +1,123 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Result 一次限流检查的结果
+type Result struct {
+	Allowed    bool          `json:"allowed"`     // 是否允许本次请求
+	Remaining  int           `json:"remaining"`   // 当前窗口剩余次数
+	RetryAfter time.Duration `json:"retry_after"` // 被拒绝时距离窗口重置的时间
+}
+
+// Limiter 固定窗口限流器
+//
+// 每次调用 Allow 计数一次，窗口内计数超过 limit 时拒绝。
+// 键由调用方构造，建议使用 cache.Keys.RateLimit 等键构建方法保持命名一致
+//
+// 使用示例：
+//
+//	limiter := ratelimit.NewMemoryLimiter()
+//	result, err := limiter.Allow(ctx, cache.Keys.RateLimit(ip, "share_password"), 5, time.Minute)
+//	if err == nil && !result.Allowed {
+//		return pkgErrors.ErrTooManyRequests
+//	}
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit int, window time.Duration) (*Result, error)
+	Reset(ctx context.Context, key string) error
+}
+
+// memoryWindow 内存限流窗口
+type memoryWindow struct {
+	count     int
+	expiresAt time.Time
+}
+
+// MemoryLimiter 进程内固定窗口限流器，用于单实例部署或Redis不可用时
+type MemoryLimiter struct {
+	mu        sync.Mutex
+	windows   map[string]*memoryWindow
+	now       func() time.Time
+	lastSweep time.Time
+}
+
+// sweepInterval 清理过期窗口的最小间隔
+const sweepInterval = time.Minute
+
+// NewMemoryLimiter 创建进程内限流器
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		windows: make(map[string]*memoryWindow),
+		now:     time.Now,
+	}
+}
+
+// Allow 计数并判断是否允许本次请求
+func (l *MemoryLimiter) Allow(_ context.Context, key string, limit int, window time.Duration) (*Result, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	w, ok := l.windows[key]
+	if !ok || !now.Before(w.expiresAt) {
+		w = &memoryWindow{expiresAt: now.Add(window)}
+		l.windows[key] = w
+	}
+	w.count++
+
+	return newResult(w.count, limit, w.expiresAt.Sub(now)), nil
+}
+
+// Reset 清除键的计数
+func (l *MemoryLimiter) Reset(_ context.Context, key string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.windows, key)
+	return nil
+}
+
+// PruneExpired 立即清理过期窗口，返回删除的数量，由定期维护任务调用
+func (l *MemoryLimiter) PruneExpired() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.prune(l.now())
+}
+
+// sweep 定期清理过期窗口，避免键无限增长
+func (l *MemoryLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.prune(now)
+}
+
+// prune 删除过期窗口并返回删除的数量，调用方需持有锁
+func (l *MemoryLimiter) prune(now time.Time) int {
+	l.lastSweep = now
+	removed := 0
+	for key, w := range l.windows {
+		if !now.Before(w.expiresAt) {
+			delete(l.windows, key)
+			removed++
+		}
+	}
+	return removed
+}
+
+// newResult 根据窗口内计数生成检查结果
+func newResult(count, limit int, ttl time.Duration) *Result {
+	remaining := limit - count
+	if remaining < 0 {
+		remaining = 0
+	}
+	result := &Result{Allowed: count <= limit, Remaining: remaining}
+	if !result.Allowed {
+		result.RetryAfter = ttl
+	}
+	return result
+}
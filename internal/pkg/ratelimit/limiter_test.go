@@ -0,0 +1,122 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryLimiter_Allow(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := NewMemoryLimiter()
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		result, err := limiter.Allow(ctx, "k", 3, time.Minute)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 2-i, result.Remaining)
+	}
+
+	now = now.Add(20 * time.Second)
+	result, err := limiter.Allow(ctx, "k", 3, time.Minute)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 40*time.Second, result.RetryAfter)
+
+	// 其他键不受影响
+	result, _ = limiter.Allow(ctx, "other", 3, time.Minute)
+	assert.True(t, result.Allowed)
+
+	// 窗口结束后重新计数
+	now = now.Add(time.Minute)
+	result, _ = limiter.Allow(ctx, "k", 3, time.Minute)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 2, result.Remaining)
+}
+
+func TestMemoryLimiter_ResetAndSweep(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := NewMemoryLimiter()
+	limiter.now = func() time.Time { return now }
+
+	limiter.Allow(ctx, "k", 1, time.Minute)
+	result, _ := limiter.Allow(ctx, "k", 1, time.Minute)
+	assert.False(t, result.Allowed)
+
+	require.NoError(t, limiter.Reset(ctx, "k"))
+	result, _ = limiter.Allow(ctx, "k", 1, time.Minute)
+	assert.True(t, result.Allowed)
+
+	now = now.Add(2 * time.Minute)
+	limiter.Allow(ctx, "fresh", 1, time.Minute)
+	assert.Len(t, limiter.windows, 1)
+}
+
+func TestMemoryCounter_Consume(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	counter := NewMemoryCounter()
+	counter.now = func() time.Time { return now }
+
+	// 以数据库中的已有次数为初始值
+	count, ok, err := counter.Consume(ctx, "k", 1, 3, time.Hour)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 2, count)
+
+	count, ok, _ = counter.Consume(ctx, "k", 1, 3, time.Hour)
+	assert.True(t, ok)
+	assert.Equal(t, 3, count)
+
+	// 达到上限后不再计数
+	count, ok, _ = counter.Consume(ctx, "k", 1, 3, time.Hour)
+	assert.False(t, ok)
+	assert.Equal(t, 3, count)
+
+	// 过期后重新从调用方提供的当前值同步
+	now = now.Add(2 * time.Hour)
+	count, ok, _ = counter.Consume(ctx, "k", 0, 3, time.Hour)
+	assert.True(t, ok)
+	assert.Equal(t, 1, count)
+}
+
+func TestMemoryBucket_Take(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	bucket := NewMemoryBucket()
+	bucket.now = func() time.Time { return now }
+
+	// 满桶允许突发
+	for i := 0; i < 3; i++ {
+		result, err := bucket.Take(ctx, "k", 60, 3)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 2-i, result.Remaining)
+	}
+
+	result, _ := bucket.Take(ctx, "k", 60, 3)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, time.Second, result.RetryAfter)
+
+	// 每秒补充一个令牌
+	now = now.Add(1500 * time.Millisecond)
+	result, _ = bucket.Take(ctx, "k", 60, 3)
+	assert.True(t, result.Allowed)
+	result, _ = bucket.Take(ctx, "k", 60, 3)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 500*time.Millisecond, result.RetryAfter)
+
+	// 未配置突发容量时容量等于每分钟令牌数
+	result, _ = bucket.Take(ctx, "other", 2, 0)
+	assert.Equal(t, 1, result.Remaining)
+
+	// 补满后清理
+	now = now.Add(time.Hour)
+	assert.Equal(t, 2, bucket.PruneExpired())
+}
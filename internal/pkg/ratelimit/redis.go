@@ -0,0 +1,145 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// RedisLimiter 基于Redis的固定窗口限流器，多实例共享计数
+type RedisLimiter struct {
+	client *redis.Client
+}
+
+// NewRedisLimiter 创建Redis限流器
+func NewRedisLimiter(client *redis.Client) *RedisLimiter {
+	return &RedisLimiter{client: client}
+}
+
+// allowScript 原子地计数并在首次计数时设置过期时间，返回计数和剩余毫秒数
+var allowScript = redis.NewScript(`
+local count = redis.call("INCR", KEYS[1])
+if count == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return {count, redis.call("PTTL", KEYS[1])}
+`)
+
+// Allow 计数并判断是否允许本次请求
+func (l *RedisLimiter) Allow(ctx context.Context, key string, limit int, window time.Duration) (*Result, error) {
+	values, err := allowScript.Run(ctx, l.client, []string{key}, window.Milliseconds()).Int64Slice()
+	if err != nil {
+		return nil, fmt.Errorf("限流计数失败: %w", err)
+	}
+	if len(values) != 2 {
+		return nil, fmt.Errorf("限流计数返回值异常: %v", values)
+	}
+
+	ttl := time.Duration(values[1]) * time.Millisecond
+	if ttl < 0 {
+		ttl = window
+	}
+	return newResult(int(values[0]), limit, ttl), nil
+}
+
+// Reset 清除键的计数
+func (l *RedisLimiter) Reset(ctx context.Context, key string) error {
+	return l.client.Del(ctx, key).Err()
+}
+
+// RedisCounter 基于Redis的总量计数器，多实例共享计数
+type RedisCounter struct {
+	client *redis.Client
+}
+
+// NewRedisCounter 创建Redis总量计数器
+func NewRedisCounter(client *redis.Client) *RedisCounter {
+	return &RedisCounter{client: client}
+}
+
+// consumeScript 原子地初始化、比较上限并计数，返回是否允许和计数后的值
+var consumeScript = redis.NewScript(`
+local count = tonumber(redis.call("GET", KEYS[1]))
+if not count then
+	count = tonumber(ARGV[1])
+	redis.call("SET", KEYS[1], count, "PX", ARGV[3])
+end
+if count >= tonumber(ARGV[2]) then
+	return {0, count}
+end
+return {1, redis.call("INCR", KEYS[1])}
+`)
+
+// Consume 未达到上限时计数加一，返回计数后的值；已达到上限时不计数并返回false
+func (c *RedisCounter) Consume(ctx context.Context, key string, current, limit int, ttl time.Duration) (int, bool, error) {
+	values, err := consumeScript.Run(ctx, c.client, []string{key}, current, limit, ttl.Milliseconds()).Int64Slice()
+	if err != nil {
+		return 0, false, fmt.Errorf("总量计数失败: %w", err)
+	}
+	if len(values) != 2 {
+		return 0, false, fmt.Errorf("总量计数返回值异常: %v", values)
+	}
+	return int(values[1]), values[0] == 1, nil
+}
+
+// RedisBucket 基于Redis的令牌桶限流器，多实例共享令牌
+type RedisBucket struct {
+	client *redis.Client
+	now    func() time.Time
+}
+
+// NewRedisBucket 创建Redis令牌桶限流器
+func NewRedisBucket(client *redis.Client) *RedisBucket {
+	return &RedisBucket{client: client, now: time.Now}
+}
+
+// takeScript 原子地补充令牌并尝试消耗一个令牌，返回是否允许、剩余令牌数和需要等待的毫秒数
+//
+// 桶补满所需时间后键自动过期，过期后按满桶重新开始
+var takeScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local interval = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local state = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if not tokens or not ts then
+	tokens = capacity
+	ts = now
+end
+if now > ts then
+	tokens = math.min(capacity, tokens + (now - ts) / interval)
+end
+local allowed = 0
+local wait = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	wait = math.ceil((1 - tokens) * interval)
+end
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "ts", now)
+redis.call("PEXPIRE", KEYS[1], math.ceil(capacity * interval))
+return {allowed, math.floor(tokens), wait}
+`)
+
+// Take 补充令牌后尝试消耗一个令牌，令牌不足时返回需要等待的时间
+func (b *RedisBucket) Take(ctx context.Context, key string, perMinute, burst int) (*Result, error) {
+	interval := max(tokenInterval(perMinute).Milliseconds(), 1)
+	capacity := bucketCapacity(perMinute, burst)
+	values, err := takeScript.Run(ctx, b.client, []string{key}, capacity, interval, b.now().UnixMilli()).Int64Slice()
+	if err != nil {
+		return nil, fmt.Errorf("令牌桶限流失败: %w", err)
+	}
+	if len(values) != 3 {
+		return nil, fmt.Errorf("令牌桶限流返回值异常: %v", values)
+	}
+
+	return &Result{
+		Allowed:    values[0] == 1,
+		Remaining:  int(values[1]),
+		RetryAfter: time.Duration(values[2]) * time.Millisecond,
+	}, nil
+}
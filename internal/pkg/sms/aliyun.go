@@ -0,0 +1,169 @@
+package sms
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1" // #nosec G505 -- 阿里云RPC签名算法规定使用HMAC-SHA1
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"cloudpan/internal/pkg/config"
+)
+
+// 阿里云短信服务默认值
+const (
+	DefaultAliyunEndpoint = "https://dysmsapi.aliyuncs.com/"
+	DefaultAliyunRegionID = "cn-hangzhou"
+	aliyunAPIVersion      = "2017-05-25"
+)
+
+// AliyunSender 通过阿里云短信服务(SendSms)发送验证码，模板变量为 code
+type AliyunSender struct {
+	endpoint        string
+	accessKeyID     string
+	accessKeySecret string
+	signName        string
+	templateCode    string
+	regionID        string
+	client          *http.Client
+	now             func() time.Time
+}
+
+// NewAliyunSender 创建阿里云短信发送渠道，client 可以为nil，此时使用 DefaultTimeout 超时的默认客户端
+func NewAliyunSender(cfg config.AliyunSMSConfig, client *http.Client) (*AliyunSender, error) {
+	if cfg.AccessKeyID == "" || cfg.AccessKeySecret == "" || cfg.SignName == "" || cfg.TemplateCode == "" {
+		return nil, errors.New("sms: aliyun access key, sign name and template code are required")
+	}
+	if client == nil {
+		client = &http.Client{Timeout: DefaultTimeout}
+	}
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = DefaultAliyunEndpoint
+	}
+	regionID := cfg.RegionID
+	if regionID == "" {
+		regionID = DefaultAliyunRegionID
+	}
+	return &AliyunSender{
+		endpoint:        endpoint,
+		accessKeyID:     cfg.AccessKeyID,
+		accessKeySecret: cfg.AccessKeySecret,
+		signName:        cfg.SignName,
+		templateCode:    cfg.TemplateCode,
+		regionID:        regionID,
+		client:          client,
+		now:             time.Now,
+	}, nil
+}
+
+// aliyunResponse SendSms 接口响应
+type aliyunResponse struct {
+	Code      string `json:"Code"`
+	Message   string `json:"Message"`
+	RequestID string `json:"RequestId"`
+	BizID     string `json:"BizId"`
+}
+
+// SendCode 发送验证码，接口返回非OK状态码时返回 ErrRejected
+func (s *AliyunSender) SendCode(ctx context.Context, phone, code string) error {
+	templateParam, err := json.Marshal(map[string]string{"code": code})
+	if err != nil {
+		return fmt.Errorf("sms: encode template param: %w", err)
+	}
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("sms: generate nonce: %w", err)
+	}
+
+	params := url.Values{
+		"AccessKeyId":      {s.accessKeyID},
+		"Action":           {"SendSms"},
+		"Format":           {"JSON"},
+		"PhoneNumbers":     {aliyunPhoneNumber(phone)},
+		"RegionId":         {s.regionID},
+		"SignName":         {s.signName},
+		"SignatureMethod":  {"HMAC-SHA1"},
+		"SignatureNonce":   {hex.EncodeToString(nonce)},
+		"SignatureVersion": {"1.0"},
+		"TemplateCode":     {s.templateCode},
+		"TemplateParam":    {string(templateParam)},
+		"Timestamp":        {s.now().UTC().Format("2006-01-02T15:04:05Z")},
+		"Version":          {aliyunAPIVersion},
+	}
+	query := aliyunCanonicalQuery(params)
+	signature := aliyunSign(http.MethodGet, query, s.accessKeySecret)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		s.endpoint+"?Signature="+aliyunPercentEncode(signature)+"&"+query, nil)
+	if err != nil {
+		return fmt.Errorf("sms: build request: %w", err)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sms: aliyun request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result aliyunResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&result); err != nil || result.Code == "" {
+		return fmt.Errorf("sms: aliyun endpoint returned status %d", resp.StatusCode)
+	}
+	if result.Code != "OK" {
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return fmt.Errorf("sms: aliyun endpoint returned %s: %s", result.Code, result.Message)
+		}
+		return fmt.Errorf("%w: %s: %s", ErrRejected, result.Code, result.Message)
+	}
+	return nil
+}
+
+// aliyunPhoneNumber 转换为阿里云的号码格式：中国大陆号码不带国家码，其他号码为国家码+号码
+func aliyunPhoneNumber(phone string) string {
+	digits := strings.TrimPrefix(phone, "+")
+	if national, ok := strings.CutPrefix(digits, "86"); ok {
+		return national
+	}
+	return digits
+}
+
+// aliyunCanonicalQuery 按参数名排序并编码，作为签名原文和请求参数
+func aliyunCanonicalQuery(params url.Values) string {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, aliyunPercentEncode(key)+"="+aliyunPercentEncode(params.Get(key)))
+	}
+	return strings.Join(pairs, "&")
+}
+
+// aliyunSign 计算RPC签名：HMAC-SHA1(AccessKeySecret+"&", Method&%2F&编码后的查询串)
+func aliyunSign(method, canonicalQuery, secret string) string {
+	stringToSign := method + "&" + aliyunPercentEncode("/") + "&" + aliyunPercentEncode(canonicalQuery)
+	mac := hmac.New(sha1.New, []byte(secret+"&"))
+	mac.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// aliyunPercentEncode 按RFC 3986编码，空格编码为%20，保留~
+func aliyunPercentEncode(value string) string {
+	encoded := url.QueryEscape(value)
+	encoded = strings.ReplaceAll(encoded, "+", "%20")
+	encoded = strings.ReplaceAll(encoded, "*", "%2A")
+	return strings.ReplaceAll(encoded, "%7E", "~")
+}
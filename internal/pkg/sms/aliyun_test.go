@@ -0,0 +1,82 @@
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"cloudpan/internal/pkg/config"
+)
+
+func TestAliyunSender_SendCode(t *testing.T) {
+	var received url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.URL.Query()
+		w.Header().Set("Content-Type", "application/json")
+		switch received.Get("PhoneNumbers") {
+		case "13800138000", "14155552671":
+			w.Write([]byte(`{"Code":"OK","Message":"OK","RequestId":"req-1","BizId":"biz-1"}`))
+		case "13900139000":
+			w.Write([]byte(`{"Code":"isv.BUSINESS_LIMIT_CONTROL","Message":"触发号码天级流控","RequestId":"req-2"}`))
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	sender, err := NewAliyunSender(config.AliyunSMSConfig{
+		AccessKeyID: "testid", AccessKeySecret: "testsecret", SignName: "云盘", TemplateCode: "SMS_154950909",
+		Endpoint: server.URL + "/",
+	}, nil)
+	require.NoError(t, err)
+	sender.now = func() time.Time { return time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC) }
+	ctx := context.Background()
+
+	require.NoError(t, sender.SendCode(ctx, "+8613800138000", "123456"))
+	assert.Equal(t, "SendSms", received.Get("Action"))
+	assert.Equal(t, "云盘", received.Get("SignName"))
+	assert.Equal(t, "2024-03-01T08:00:00Z", received.Get("Timestamp"))
+	assert.Equal(t, DefaultAliyunRegionID, received.Get("RegionId"))
+	var param map[string]string
+	require.NoError(t, json.Unmarshal([]byte(received.Get("TemplateParam")), &param))
+	assert.Equal(t, "123456", param["code"])
+
+	// 服务端按收到的参数重新计算签名
+	signature := received.Get("Signature")
+	received.Del("Signature")
+	assert.Equal(t, aliyunSign(http.MethodGet, aliyunCanonicalQuery(received), "testsecret"), signature)
+
+	// 国际号码带国家码，不带+
+	require.NoError(t, sender.SendCode(ctx, "+14155552671", "123456"))
+
+	err = sender.SendCode(ctx, "+8613900139000", "123456")
+	assert.ErrorIs(t, err, ErrRejected)
+	assert.Contains(t, err.Error(), "BUSINESS_LIMIT_CONTROL")
+
+	err = sender.SendCode(ctx, "+8613700137000", "123456")
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrRejected)
+}
+
+func TestAliyunSign(t *testing.T) {
+	// 阿里云签名机制文档中的示例
+	params := url.Values{
+		"AccessKeyId":      {"testid"},
+		"Action":           {"DescribeRegions"},
+		"Format":           {"XML"},
+		"SignatureMethod":  {"HMAC-SHA1"},
+		"SignatureNonce":   {"3ee8c1b8-83d3-44af-a94f-4e0ad82fd6cf"},
+		"SignatureVersion": {"1.0"},
+		"Timestamp":        {"2016-02-23T12:46:24Z"},
+		"Version":          {"2014-05-26"},
+	}
+	assert.Equal(t, "OLeaidS1JvxuMvnyHOwuJ+uX5qY=", aliyunSign(http.MethodGet, aliyunCanonicalQuery(params), "testsecret"))
+	assert.Equal(t, "a%20b%2A~%2F", aliyunPercentEncode("a b*~/"))
+}
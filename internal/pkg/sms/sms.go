@@ -0,0 +1,107 @@
+// Package sms 短信验证码发送
+//
+// 支持阿里云短信服务和 Twilio，由配置 third_party.sms.provider 选择。
+// 手机号统一规范化为 E.164 格式(+国家码+号码)后保存、限流和发送，
+// 同一号码的不同写法(带空格、省略国家码等)视为同一目标
+package sms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"cloudpan/internal/pkg/config"
+)
+
+// DefaultTimeout 调用提供方接口的默认超时
+const DefaultTimeout = 10 * time.Second
+
+// maxResponseSize 提供方接口响应体的大小上限
+const maxResponseSize = 64 << 10
+
+// 短信提供方
+const (
+	ProviderAliyun = "aliyun"
+	ProviderTwilio = "twilio"
+)
+
+// 发送错误
+var (
+	ErrInvalidPhone = errors.New("sms: invalid phone number")         // 手机号格式不正确
+	ErrRejected     = errors.New("sms: message rejected by provider") // 提供方拒绝发送，如号码不可达或触发提供方限流
+)
+
+// Sender 短信验证码发送接口
+type Sender interface {
+	// SendCode 向E.164格式的手机号发送验证码，提供方拒绝发送时返回 ErrRejected，
+	// 提供方不可用时返回其他错误
+	SendCode(ctx context.Context, phone, code string) error
+}
+
+// FromConfig 根据配置创建短信发送渠道，未启用时返回nil
+func FromConfig(cfg config.SMSConfig) (Sender, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	client := &http.Client{Timeout: timeout}
+
+	switch cfg.Provider {
+	case ProviderAliyun:
+		return NewAliyunSender(cfg.Aliyun, client)
+	case ProviderTwilio:
+		return NewTwilioSender(cfg.Twilio, client)
+	default:
+		return nil, fmt.Errorf("sms: unsupported provider %q", cfg.Provider)
+	}
+}
+
+// E.164 号码长度限制(不含+)
+const (
+	minPhoneDigits = 8
+	maxPhoneDigits = 15
+)
+
+// NormalizePhone 将手机号规范化为E.164格式
+//
+// 忽略空格、短横线、括号和点；以+或00开头的号码视为已带国家码，
+// 其余号码去掉国内长途前缀0后加上 defaultCountryCode，未配置默认国家码时返回 ErrInvalidPhone。
+// 中国大陆(+86)号码额外校验为1开头的11位手机号
+func NormalizePhone(phone, defaultCountryCode string) (string, error) {
+	phone = strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '(', ')', '.':
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(phone))
+
+	var digits string
+	switch {
+	case strings.HasPrefix(phone, "+"):
+		digits = phone[1:]
+	case strings.HasPrefix(phone, "00"):
+		digits = phone[2:]
+	default:
+		countryCode := strings.TrimPrefix(strings.TrimSpace(defaultCountryCode), "+")
+		if countryCode == "" {
+			return "", fmt.Errorf("%w: country code required", ErrInvalidPhone)
+		}
+		digits = countryCode + strings.TrimPrefix(phone, "0")
+	}
+
+	if len(digits) < minPhoneDigits || len(digits) > maxPhoneDigits ||
+		strings.Trim(digits, "0123456789") != "" || digits[0] == '0' {
+		return "", ErrInvalidPhone
+	}
+	if national, ok := strings.CutPrefix(digits, "86"); ok && (len(national) != 11 || national[0] != '1') {
+		return "", ErrInvalidPhone
+	}
+	return "+" + digits, nil
+}
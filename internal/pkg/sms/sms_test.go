@@ -0,0 +1,64 @@
+package sms
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"cloudpan/internal/pkg/config"
+)
+
+func TestNormalizePhone(t *testing.T) {
+	tests := []struct {
+		phone       string
+		countryCode string
+		want        string
+		wantErr     bool
+	}{
+		{phone: "138 0013 8000", countryCode: "86", want: "+8613800138000"},
+		{phone: "+86 138-0013-8000", want: "+8613800138000"},
+		{phone: "008613800138000", want: "+8613800138000"},
+		{phone: "(415) 555-2671", countryCode: "+1", want: "+14155552671"},
+		{phone: "07911 123456", countryCode: "44", want: "+447911123456"},
+		{phone: "13800138000", wantErr: true},                    // 未配置默认国家码
+		{phone: "+86 2388001234", wantErr: true},                 // 中国大陆号码不是手机号
+		{phone: "+1 415 555 267a", wantErr: true},                // 含非数字字符
+		{phone: "+1234567", wantErr: true},                       // 过短
+		{phone: "+1234567890123456", wantErr: true},              // 超过15位
+		{phone: "", countryCode: "86", wantErr: true},            // 空号码
+		{phone: "+0123456789", countryCode: "86", wantErr: true}, // 国家码不能以0开头
+	}
+	for _, tt := range tests {
+		got, err := NormalizePhone(tt.phone, tt.countryCode)
+		if tt.wantErr {
+			assert.ErrorIs(t, err, ErrInvalidPhone, "%q", tt.phone)
+			continue
+		}
+		require.NoError(t, err, "%q", tt.phone)
+		assert.Equal(t, tt.want, got)
+	}
+}
+
+func TestFromConfig(t *testing.T) {
+	sender, err := FromConfig(config.SMSConfig{Provider: ProviderAliyun})
+	require.NoError(t, err)
+	assert.Nil(t, sender, "未启用时不创建发送渠道")
+
+	sender, err = FromConfig(config.SMSConfig{Enabled: true, Provider: ProviderAliyun, Aliyun: config.AliyunSMSConfig{
+		AccessKeyID: "ak", AccessKeySecret: "sk", SignName: "CloudPan", TemplateCode: "SMS_1",
+	}})
+	require.NoError(t, err)
+	assert.IsType(t, &AliyunSender{}, sender)
+
+	sender, err = FromConfig(config.SMSConfig{Enabled: true, Provider: ProviderTwilio, Twilio: config.TwilioSMSConfig{
+		AccountSID: "AC1", AuthToken: "token", From: "+15005550006",
+	}})
+	require.NoError(t, err)
+	assert.IsType(t, &TwilioSender{}, sender)
+
+	_, err = FromConfig(config.SMSConfig{Enabled: true, Provider: ProviderTwilio})
+	assert.Error(t, err)
+	_, err = FromConfig(config.SMSConfig{Enabled: true, Provider: "smtp"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,105 @@
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"cloudpan/internal/pkg/config"
+)
+
+// Twilio 默认值
+const (
+	DefaultTwilioBaseURL = "https://api.twilio.com"
+	DefaultTwilioBody    = "Your verification code is {code}"
+)
+
+// TwilioSender 通过 Twilio Messages 接口发送验证码
+type TwilioSender struct {
+	baseURL             string
+	accountSID          string
+	authToken           string
+	from                string
+	messagingServiceSID string
+	body                string
+	client              *http.Client
+}
+
+// NewTwilioSender 创建Twilio发送渠道，client 可以为nil，此时使用 DefaultTimeout 超时的默认客户端
+func NewTwilioSender(cfg config.TwilioSMSConfig, client *http.Client) (*TwilioSender, error) {
+	if cfg.AccountSID == "" || cfg.AuthToken == "" {
+		return nil, errors.New("sms: twilio account sid and auth token are required")
+	}
+	if cfg.From == "" && cfg.MessagingServiceSID == "" {
+		return nil, errors.New("sms: twilio from number or messaging service sid is required")
+	}
+	if client == nil {
+		client = &http.Client{Timeout: DefaultTimeout}
+	}
+	baseURL := strings.TrimRight(cfg.BaseURL, "/")
+	if baseURL == "" {
+		baseURL = DefaultTwilioBaseURL
+	}
+	body := cfg.Body
+	if !strings.Contains(body, "{code}") {
+		body = DefaultTwilioBody
+	}
+	return &TwilioSender{
+		baseURL:             baseURL,
+		accountSID:          cfg.AccountSID,
+		authToken:           cfg.AuthToken,
+		from:                cfg.From,
+		messagingServiceSID: cfg.MessagingServiceSID,
+		body:                body,
+		client:              client,
+	}, nil
+}
+
+// twilioError Twilio 接口错误响应
+type twilioError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// SendCode 发送验证码，接口返回4xx时返回 ErrRejected
+func (s *TwilioSender) SendCode(ctx context.Context, phone, code string) error {
+	form := url.Values{
+		"To":   {phone},
+		"Body": {strings.ReplaceAll(s.body, "{code}", code)},
+	}
+	if s.messagingServiceSID != "" {
+		form.Set("MessagingServiceSid", s.messagingServiceSID)
+	} else {
+		form.Set("From", s.from)
+	}
+
+	endpoint := s.baseURL + "/2010-04-01/Accounts/" + url.PathEscape(s.accountSID) + "/Messages.json"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("sms: build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(s.accountSID, s.authToken)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sms: twilio request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusCreated {
+		return nil
+	}
+	var result twilioError
+	_ = json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&result)
+	if resp.StatusCode >= http.StatusBadRequest && resp.StatusCode < http.StatusInternalServerError &&
+		resp.StatusCode != http.StatusUnauthorized && resp.StatusCode != http.StatusForbidden {
+		return fmt.Errorf("%w: %d: %s", ErrRejected, result.Code, result.Message)
+	}
+	return fmt.Errorf("sms: twilio endpoint returned status %d: %s", resp.StatusCode, result.Message)
+}
@@ -0,0 +1,73 @@
+package sms
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"cloudpan/internal/pkg/config"
+)
+
+func TestTwilioSender_SendCode(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/2010-04-01/Accounts/AC123/Messages.json", r.URL.Path)
+		user, password, ok := r.BasicAuth()
+		if !ok || user != "AC123" || password != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"code":20003,"message":"Authenticate"}`))
+			return
+		}
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "MG123", r.PostForm.Get("MessagingServiceSid"))
+		assert.Empty(t, r.PostForm.Get("From"))
+		switch r.PostForm.Get("To") {
+		case "+14155552671":
+			assert.Equal(t, "CloudPan code: 123456", r.PostForm.Get("Body"))
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"sid":"SM123","status":"queued"}`))
+		case "+15005550001":
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"code":21211,"message":"The 'To' number is not a valid phone number.","status":400}`))
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := config.TwilioSMSConfig{
+		AccountSID: "AC123", AuthToken: "token", From: "+15005550006", MessagingServiceSID: "MG123",
+		BaseURL: server.URL + "/", Body: "CloudPan code: {code}",
+	}
+	sender, err := NewTwilioSender(cfg, nil)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	require.NoError(t, sender.SendCode(ctx, "+14155552671", "123456"))
+
+	err = sender.SendCode(ctx, "+15005550001", "123456")
+	assert.ErrorIs(t, err, ErrRejected)
+	assert.Contains(t, err.Error(), "21211")
+
+	err = sender.SendCode(ctx, "+15005550009", "123456")
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrRejected, "提供方不可用不是拒绝发送")
+
+	// 凭据错误是配置问题，不视为号码被拒
+	cfg.AuthToken = "wrong"
+	sender, err = NewTwilioSender(cfg, nil)
+	require.NoError(t, err)
+	err = sender.SendCode(ctx, "+14155552671", "123456")
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrRejected)
+}
+
+func TestNewTwilioSender_DefaultBody(t *testing.T) {
+	sender, err := NewTwilioSender(config.TwilioSMSConfig{AccountSID: "AC1", AuthToken: "token", From: "+15005550006", Body: "no placeholder"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultTwilioBody, sender.body)
+	assert.Equal(t, DefaultTwilioBaseURL, sender.baseURL)
+}
@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"fmt"
+
+	"cloudpan/internal/pkg/config"
+)
+
+// NewFromConfig 按配置的存储后端创建文件存储
+//
+// storage.backend 为空或local时使用本地存储，为s3时使用S3兼容存储；
+// 已设置全局存储健康检查时返回其按熔断状态读写、读取可切换到副本的存储
+func NewFromConfig(cfg config.StorageConfig) (Storage, error) {
+	if monitor := DefaultHealthMonitor(); monitor != nil {
+		return monitor.Storage(), nil
+	}
+	return NewPrimaryFromConfig(cfg)
+}
+
+// NewPrimaryFromConfig 按配置创建主存储，不经过健康检查
+func NewPrimaryFromConfig(cfg config.StorageConfig) (Storage, error) {
+	return newStorage(cfg.Backend, cfg.Local.RootPath, cfg.S3)
+}
+
+// NewReplicaFromConfig 按配置创建只读副本，未配置副本时返回nil
+func NewReplicaFromConfig(cfg config.StorageConfig) (Storage, error) {
+	if cfg.Replica.Backend == "" {
+		return nil, nil
+	}
+	return newStorage(cfg.Replica.Backend, cfg.Replica.RootPath, cfg.Replica.S3)
+}
+
+// newStorage 创建指定类型的存储后端
+func newStorage(backend, rootPath string, s3 config.S3StorageConfig) (Storage, error) {
+	switch backend {
+	case "", StorageTypeLocal:
+		store, err := NewLocalStorage(rootPath)
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	case StorageTypeS3:
+		store, err := NewS3Storage(S3OptionsFromStorageConfig(s3), s3.PartSize, nil)
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	default:
+		return nil, fmt.Errorf("不支持的存储后端: %s", backend)
+	}
+}
@@ -0,0 +1,186 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"go.uber.org/zap"
+)
+
+// FailoverStorage 按熔断状态读写的存储，由 HealthMonitor 创建
+//
+// 写入和删除只发往主存储，主存储熔断时直接返回 ErrBackendUnavailable；
+// 读取按 主存储 → 副本 → 错误 的顺序：主存储熔断或读取失败(对象不存在除外)时改从副本读取，
+// 副本也不可用时立即返回 ErrBackendUnavailable，不再等待后端超时
+type FailoverStorage struct {
+	primary *Backend
+	replica *Backend
+	logger  *zap.Logger
+}
+
+// Unwrap 返回主存储，调用方据此判断后端支持的可选能力(如 Composer)
+func (s *FailoverStorage) Unwrap() Storage {
+	return s.primary.store
+}
+
+// Type 返回主存储的类型
+func (s *FailoverStorage) Type() string {
+	return s.primary.store.Type()
+}
+
+// Put 写入主存储
+func (s *FailoverStorage) Put(ctx context.Context, path string, reader io.Reader, size int64) error {
+	return s.write(ctx, func(store Storage) error {
+		return store.Put(ctx, path, reader, size)
+	})
+}
+
+// Create 在主存储上打开流式写入器
+func (s *FailoverStorage) Create(ctx context.Context, path string) (ObjectWriter, error) {
+	var writer ObjectWriter
+	err := s.write(ctx, func(store Storage) error {
+		var err error
+		writer, err = store.Create(ctx, path)
+		return err
+	})
+	return writer, err
+}
+
+// Delete 删除主存储上的对象
+func (s *FailoverStorage) Delete(ctx context.Context, path string) error {
+	return s.write(ctx, func(store Storage) error {
+		return store.Delete(ctx, path)
+	})
+}
+
+// Open 打开对象用于读取，主存储不可用时从副本读取
+func (s *FailoverStorage) Open(ctx context.Context, path string) (io.ReadCloser, error) {
+	var reader io.ReadCloser
+	err := s.read(ctx, "open", path, func(store Storage) error {
+		var err error
+		reader, err = store.Open(ctx, path)
+		return err
+	})
+	return reader, err
+}
+
+// OpenRange 按范围读取对象，主存储不可用时从副本读取
+//
+// 后端不支持范围读取时打开整个对象，可定位时直接定位，否则丢弃offset之前的数据
+func (s *FailoverStorage) OpenRange(ctx context.Context, path string, offset, length int64) (io.ReadCloser, error) {
+	var reader io.ReadCloser
+	err := s.read(ctx, "open_range", path, func(store Storage) error {
+		var err error
+		reader, err = openRange(ctx, store, path, offset, length)
+		return err
+	})
+	return reader, err
+}
+
+// Stat 获取对象信息，主存储不可用时从副本获取
+func (s *FailoverStorage) Stat(ctx context.Context, path string) (*ObjectInfo, error) {
+	var info *ObjectInfo
+	err := s.read(ctx, "stat", path, func(store Storage) error {
+		var err error
+		info, err = store.Stat(ctx, path)
+		return err
+	})
+	return info, err
+}
+
+// Exists 检查对象是否存在，主存储不可用时检查副本
+func (s *FailoverStorage) Exists(ctx context.Context, path string) (bool, error) {
+	var exists bool
+	err := s.read(ctx, "exists", path, func(store Storage) error {
+		var err error
+		exists, err = store.Exists(ctx, path)
+		return err
+	})
+	return exists, err
+}
+
+// write 在主存储上执行写操作
+func (s *FailoverStorage) write(ctx context.Context, fn func(Storage) error) error {
+	if !s.primary.allow() {
+		return fmt.Errorf("主存储暂时不可用: %w", ErrBackendUnavailable)
+	}
+	err := fn(s.primary.store)
+	s.primary.record(ctx, err)
+	return err
+}
+
+// read 按 主存储 → 副本 的顺序执行读操作
+//
+// 对象不存在是确定的结果，不切换到副本；副本由复制同步，可能落后于主存储
+func (s *FailoverStorage) read(ctx context.Context, op, path string, fn func(Storage) error) error {
+	var primaryErr error
+	if s.primary.allow() {
+		primaryErr = fn(s.primary.store)
+		if !s.primary.record(ctx, primaryErr) {
+			return primaryErr
+		}
+	} else {
+		primaryErr = ErrBackendUnavailable
+	}
+
+	if s.replica == nil || !s.replica.allow() {
+		return fmt.Errorf("存储暂时不可用: %v: %w", primaryErr, ErrBackendUnavailable)
+	}
+	s.replica.failover()
+	err := fn(s.replica.store)
+	if s.replica.record(ctx, err) {
+		s.logger.Warn("Storage read failed on primary and replica",
+			zap.String("op", op),
+			zap.String("path", path),
+			zap.NamedError("primary_error", primaryErr),
+			zap.Error(err))
+		return fmt.Errorf("存储暂时不可用: %v: %w", err, ErrBackendUnavailable)
+	}
+	return err
+}
+
+// failover 记录一次转到该后端的读取
+func (b *Backend) failover() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.health.Failovers++
+}
+
+// openRange 在指定后端上按范围读取对象
+func openRange(ctx context.Context, store Storage, path string, offset, length int64) (io.ReadCloser, error) {
+	if ranger, ok := store.(RangeOpener); ok {
+		return ranger.OpenRange(ctx, path, offset, length)
+	}
+
+	reader, err := store.Open(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	if offset > 0 {
+		if seeker, ok := reader.(io.Seeker); ok {
+			_, err = seeker.Seek(offset, io.SeekStart)
+		} else {
+			_, err = io.CopyN(io.Discard, reader, offset)
+		}
+		if err != nil {
+			_ = reader.Close()
+			return nil, fmt.Errorf("定位存储对象失败: %w", err)
+		}
+	}
+	return &limitedReadCloser{Reader: io.LimitReader(reader, length), Closer: reader}, nil
+}
+
+// limitedReadCloser 只读取指定长度并关闭底层读取器
+type limitedReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// Unwrap 返回包装存储下的实际存储，未包装时返回store本身
+func Unwrap(store Storage) Storage {
+	if wrapper, ok := store.(interface{ Unwrap() Storage }); ok {
+		return wrapper.Unwrap()
+	}
+	return store
+}
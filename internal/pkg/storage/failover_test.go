@@ -0,0 +1,170 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyStorage 可以模拟后端故障的存储
+type flakyStorage struct {
+	Storage
+	down  bool
+	calls int
+}
+
+var errBackendDown = errors.New("connection refused")
+
+func (s *flakyStorage) Open(ctx context.Context, path string) (io.ReadCloser, error) {
+	s.calls++
+	if s.down {
+		return nil, errBackendDown
+	}
+	return s.Storage.Open(ctx, path)
+}
+
+func (s *flakyStorage) Stat(ctx context.Context, path string) (*ObjectInfo, error) {
+	s.calls++
+	if s.down {
+		return nil, errBackendDown
+	}
+	return s.Storage.Stat(ctx, path)
+}
+
+func (s *flakyStorage) Exists(ctx context.Context, path string) (bool, error) {
+	s.calls++
+	if s.down {
+		return false, errBackendDown
+	}
+	return s.Storage.Exists(ctx, path)
+}
+
+func (s *flakyStorage) Put(ctx context.Context, path string, reader io.Reader, size int64) error {
+	s.calls++
+	if s.down {
+		return errBackendDown
+	}
+	return s.Storage.Put(ctx, path, reader, size)
+}
+
+func newFlakyStorage(t *testing.T, objects map[string]string) *flakyStorage {
+	t.Helper()
+	store, err := NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	for path, content := range objects {
+		require.NoError(t, store.Put(context.Background(), path, strings.NewReader(content), int64(len(content))))
+	}
+	return &flakyStorage{Storage: store}
+}
+
+func readAll(t *testing.T, reader io.ReadCloser, err error) string {
+	t.Helper()
+	require.NoError(t, err)
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestFailoverStorage_ReadsFallBackToReplica(t *testing.T) {
+	ctx := context.Background()
+	primary := newFlakyStorage(t, map[string]string{"a.txt": "primary"})
+	replica := newFlakyStorage(t, map[string]string{"a.txt": "replica"})
+	monitor := NewHealthMonitor(primary, replica, HealthOptions{FailureThreshold: 2, OpenDuration: time.Minute}, nil)
+	store := monitor.Storage()
+
+	reader, err := store.Open(ctx, "a.txt")
+	assert.Equal(t, "primary", readAll(t, reader, err))
+
+	// 对象不存在是确定的结果，不切换到副本
+	_, err = store.Stat(ctx, "missing.txt")
+	assert.True(t, IsNotFound(err))
+	assert.Zero(t, replica.calls)
+
+	// 主存储读取失败时从副本读取，连续失败达到阈值后熔断
+	primary.down = true
+	for i := 0; i < 2; i++ {
+		reader, err = store.Open(ctx, "a.txt")
+		assert.Equal(t, "replica", readAll(t, reader, err))
+	}
+	status := monitor.Status()
+	assert.Equal(t, CircuitOpen, status[0].State)
+	assert.Equal(t, int64(2), status[1].Failovers)
+
+	// 熔断期间不再访问主存储
+	calls := primary.calls
+	reader, err = store.OpenRange(ctx, "a.txt", 2, 3)
+	assert.Equal(t, "pli", readAll(t, reader, err))
+	assert.Equal(t, calls, primary.calls)
+	assert.Equal(t, int64(1), monitor.Status()[0].Rejected)
+	assert.True(t, monitor.Readable())
+
+	// 写入不切换到副本
+	err = store.Put(ctx, "b.txt", strings.NewReader("b"), 1)
+	assert.ErrorIs(t, err, ErrBackendUnavailable)
+	exists, err := replica.Storage.Exists(ctx, "b.txt")
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestFailoverStorage_FailsFastWithoutReplica(t *testing.T) {
+	ctx := context.Background()
+	primary := newFlakyStorage(t, map[string]string{"a.txt": "primary"})
+	monitor := NewHealthMonitor(primary, nil, HealthOptions{FailureThreshold: 1, OpenDuration: time.Minute}, nil)
+	store := monitor.Storage()
+
+	primary.down = true
+	_, err := store.Stat(ctx, "a.txt")
+	assert.ErrorIs(t, err, ErrBackendUnavailable)
+	assert.False(t, monitor.Readable())
+
+	calls := primary.calls
+	_, err = store.Open(ctx, "a.txt")
+	assert.ErrorIs(t, err, ErrBackendUnavailable)
+	assert.Equal(t, calls, primary.calls, "熔断期间直接返回错误")
+}
+
+func TestHealthMonitor_CircuitRecovery(t *testing.T) {
+	ctx := context.Background()
+	primary := newFlakyStorage(t, map[string]string{"a.txt": "primary"})
+	monitor := NewHealthMonitor(primary, nil, HealthOptions{FailureThreshold: 2, OpenDuration: time.Minute}, nil)
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	monitor.primary.now = func() time.Time { return now }
+
+	primary.down = true
+	monitor.Probe(ctx)
+	assert.Equal(t, CircuitClosed, monitor.Status()[0].State)
+	monitor.Probe(ctx)
+	status := monitor.Status()[0]
+	assert.Equal(t, CircuitOpen, status.State)
+	assert.Equal(t, 2, status.ConsecutiveFailures)
+	assert.Contains(t, status.LastError, "connection refused")
+	require.NotNil(t, status.LastProbeAt)
+
+	// 熔断时长过后放行请求试探，试探失败重新熔断
+	now = now.Add(time.Minute)
+	assert.Equal(t, CircuitHalfOpen, monitor.Status()[0].State)
+	_, err := monitor.Storage().Stat(ctx, "a.txt")
+	assert.ErrorIs(t, err, ErrBackendUnavailable)
+	assert.Equal(t, CircuitOpen, monitor.Status()[0].State)
+
+	// 探测成功后恢复
+	primary.down = false
+	monitor.Probe(ctx)
+	assert.Equal(t, CircuitClosed, monitor.Status()[0].State)
+	reader, err := monitor.Storage().Open(ctx, "a.txt")
+	assert.Equal(t, "primary", readAll(t, reader, err))
+}
+
+func TestUnwrap(t *testing.T) {
+	primary := newFlakyStorage(t, nil)
+	monitor := NewHealthMonitor(primary, nil, HealthOptions{}, nil)
+	assert.Same(t, primary, Unwrap(monitor.Storage()))
+	assert.Same(t, primary, Unwrap(primary))
+}
@@ -0,0 +1,325 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/config"
+)
+
+// ErrBackendUnavailable 存储后端熔断或全部不可用
+var ErrBackendUnavailable = errors.New("storage backend unavailable")
+
+// 熔断状态
+const (
+	CircuitClosed   = "closed"    // 正常放行请求
+	CircuitOpen     = "open"      // 熔断，直接拒绝请求
+	CircuitHalfOpen = "half_open" // 熔断时长已过，放行请求试探
+)
+
+// 后端角色
+const (
+	RolePrimary = "primary" // 主存储，读写
+	RoleReplica = "replica" // 只读副本
+)
+
+// 健康检查默认值
+const (
+	DefaultProbeInterval    = 15 * time.Second
+	DefaultProbeTimeout     = 5 * time.Second
+	DefaultFailureThreshold = 3
+	DefaultOpenDuration     = 30 * time.Second
+
+	// healthProbePath 探测时查询的对象路径，对象不存在时后端正常返回不存在
+	healthProbePath = ".health/probe"
+)
+
+// HealthOptions 存储健康检查选项
+type HealthOptions struct {
+	ProbeInterval    time.Duration // 探测间隔
+	ProbeTimeout     time.Duration // 单次探测超时
+	FailureThreshold int           // 连续失败多少次后熔断
+	OpenDuration     time.Duration // 熔断后多久放行请求试探
+}
+
+// HealthOptionsFromConfig 从健康检查配置生成选项
+func HealthOptionsFromConfig(cfg config.StorageHealthConfig) HealthOptions {
+	return HealthOptions{
+		ProbeInterval:    cfg.ProbeInterval,
+		ProbeTimeout:     cfg.ProbeTimeout,
+		FailureThreshold: cfg.FailureThreshold,
+		OpenDuration:     cfg.OpenDuration,
+	}
+}
+
+// BackendHealth 存储后端的健康状况和请求指标
+type BackendHealth struct {
+	Role                string     `json:"role"`                      // 角色(primary/replica)
+	Type                string     `json:"type"`                      // 存储类型
+	State               string     `json:"state"`                     // 熔断状态(closed/open/half_open)
+	ConsecutiveFailures int        `json:"consecutive_failures"`      // 连续失败次数
+	LastProbeAt         *time.Time `json:"last_probe_at,omitempty"`   // 最近一次探测时间
+	LastProbeLatencyMs  int64      `json:"last_probe_latency_ms"`     // 最近一次探测耗时(毫秒)
+	LastError           string     `json:"last_error,omitempty"`      // 最近一次失败的错误
+	LastFailureAt       *time.Time `json:"last_failure_at,omitempty"` // 最近一次失败时间
+	OpenedAt            *time.Time `json:"opened_at,omitempty"`       // 最近一次熔断时间
+	Requests            int64      `json:"requests"`                  // 经过熔断器的请求数
+	Failures            int64      `json:"failures"`                  // 失败的请求和探测数
+	Rejected            int64      `json:"rejected"`                  // 熔断期间直接拒绝的请求数
+	Failovers           int64      `json:"failovers"`                 // 主存储不可用时转到该后端的读取数
+}
+
+// Backend 带熔断器的存储后端
+//
+// 请求和探测的结果都计入熔断器：连续失败达到阈值时熔断，熔断时长过后放行请求试探，
+// 试探或探测成功后恢复；对象不存在和调用方取消不计为失败
+type Backend struct {
+	store   Storage
+	options HealthOptions
+	now     func() time.Time
+
+	mu     sync.Mutex
+	health BackendHealth
+}
+
+// newBackend 创建带熔断器的存储后端
+func newBackend(role string, store Storage, options HealthOptions, now func() time.Time) *Backend {
+	return &Backend{
+		store:   store,
+		options: options,
+		now:     now,
+		health:  BackendHealth{Role: role, Type: store.Type(), State: CircuitClosed},
+	}
+}
+
+// Storage 返回底层存储
+func (b *Backend) Storage() Storage {
+	return b.store
+}
+
+// Health 返回后端的健康状况
+func (b *Backend) Health() BackendHealth {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refresh()
+	return b.health
+}
+
+// Available 返回后端当前是否放行请求，不计入请求数
+func (b *Backend) Available() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refresh()
+	return b.health.State != CircuitOpen
+}
+
+// allow 检查熔断器是否放行请求，拒绝时计入拒绝数
+func (b *Backend) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refresh()
+	if b.health.State == CircuitOpen {
+		b.health.Rejected++
+		return false
+	}
+	b.health.Requests++
+	return true
+}
+
+// refresh 熔断时长已过时转为半开
+func (b *Backend) refresh() {
+	if b.health.State == CircuitOpen && b.health.OpenedAt != nil && b.now().Sub(*b.health.OpenedAt) >= b.options.OpenDuration {
+		b.health.State = CircuitHalfOpen
+	}
+}
+
+// record 记录请求或探测结果，返回err是否计为后端故障
+func (b *Backend) record(ctx context.Context, err error) bool {
+	if err != nil && (IsNotFound(err) || errors.Is(err, ErrInvalidPath) || ctx.Err() != nil) {
+		// 对象不存在、路径非法和调用方取消说明后端可以正常响应
+		err = nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	if err == nil {
+		b.health.ConsecutiveFailures = 0
+		b.health.State = CircuitClosed
+		return false
+	}
+
+	b.health.Failures++
+	b.health.ConsecutiveFailures++
+	b.health.LastError = err.Error()
+	b.health.LastFailureAt = &now
+	if b.health.State == CircuitHalfOpen || b.health.ConsecutiveFailures >= b.options.FailureThreshold {
+		b.health.State = CircuitOpen
+		b.health.OpenedAt = &now
+	}
+	return true
+}
+
+// probe 探测后端一次
+func (b *Backend) probe(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, b.options.ProbeTimeout)
+	defer cancel()
+
+	start := b.now()
+	_, err := b.store.Exists(ctx, healthProbePath)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		// 探测超时计为后端故障
+		err = context.DeadlineExceeded
+		ctx = context.Background()
+	}
+	b.record(ctx, err)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.health.LastProbeAt = &start
+	b.health.LastProbeLatencyMs = b.now().Sub(start).Milliseconds()
+	return err
+}
+
+// HealthMonitor 存储后端健康检查
+//
+// 管理主存储和可选的只读副本，定期探测并维护各自的熔断状态；
+// Storage 返回按熔断状态写入主存储、读取时在主存储和副本之间切换的存储
+//
+// 使用示例：
+//
+//	monitor := storage.NewHealthMonitor(primary, replica, storage.HealthOptionsFromConfig(cfg.Storage.Health), logger)
+//	go monitor.Run(ctx)
+//	storage.SetDefaultHealthMonitor(monitor)
+//	status := monitor.Status()
+type HealthMonitor struct {
+	primary *Backend
+	replica *Backend
+	options HealthOptions
+	logger  *zap.Logger
+	store   *FailoverStorage
+}
+
+// NewHealthMonitor 创建存储健康检查，replica为nil时不使用副本
+func NewHealthMonitor(primary, replica Storage, options HealthOptions, logger *zap.Logger) *HealthMonitor {
+	if options.ProbeInterval <= 0 {
+		options.ProbeInterval = DefaultProbeInterval
+	}
+	if options.ProbeTimeout <= 0 {
+		options.ProbeTimeout = DefaultProbeTimeout
+	}
+	if options.FailureThreshold <= 0 {
+		options.FailureThreshold = DefaultFailureThreshold
+	}
+	if options.OpenDuration <= 0 {
+		options.OpenDuration = DefaultOpenDuration
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	m := &HealthMonitor{
+		primary: newBackend(RolePrimary, primary, options, time.Now),
+		options: options,
+		logger:  logger,
+	}
+	if replica != nil {
+		m.replica = newBackend(RoleReplica, replica, options, time.Now)
+	}
+	m.store = &FailoverStorage{primary: m.primary, replica: m.replica, logger: logger}
+	return m
+}
+
+// Storage 返回按熔断状态读写的存储
+func (m *HealthMonitor) Storage() *FailoverStorage {
+	return m.store
+}
+
+// Run 定期探测各后端直到ctx取消
+func (m *HealthMonitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.options.ProbeInterval)
+	defer ticker.Stop()
+	for {
+		m.Probe(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Probe 探测各后端一次，熔断状态变化时记录日志
+func (m *HealthMonitor) Probe(ctx context.Context) {
+	for _, backend := range m.backends() {
+		before := backend.Health().State
+		err := backend.probe(ctx)
+		after := backend.Health()
+		if before == after.State {
+			continue
+		}
+		if after.State == CircuitOpen {
+			m.logger.Error("Storage backend circuit opened",
+				zap.String("role", after.Role),
+				zap.String("type", after.Type),
+				zap.Error(err))
+		} else {
+			m.logger.Info("Storage backend circuit state changed",
+				zap.String("role", after.Role),
+				zap.String("type", after.Type),
+				zap.String("from", before),
+				zap.String("to", after.State))
+		}
+	}
+}
+
+// Status 返回各后端的健康状况，主存储在前
+func (m *HealthMonitor) Status() []BackendHealth {
+	backends := m.backends()
+	status := make([]BackendHealth, len(backends))
+	for i, backend := range backends {
+		status[i] = backend.Health()
+	}
+	return status
+}
+
+// Readable 返回是否有后端可以提供读取
+func (m *HealthMonitor) Readable() bool {
+	for _, backend := range m.backends() {
+		if backend.Available() {
+			return true
+		}
+	}
+	return false
+}
+
+// backends 返回全部后端，主存储在前
+func (m *HealthMonitor) backends() []*Backend {
+	if m.replica == nil {
+		return []*Backend{m.primary}
+	}
+	return []*Backend{m.primary, m.replica}
+}
+
+var (
+	defaultMonitorMu sync.RWMutex
+	defaultMonitor   *HealthMonitor
+)
+
+// SetDefaultHealthMonitor 设置全局存储健康检查，设置后 NewFromConfig 返回其按熔断状态读写的存储
+func SetDefaultHealthMonitor(monitor *HealthMonitor) {
+	defaultMonitorMu.Lock()
+	defer defaultMonitorMu.Unlock()
+	defaultMonitor = monitor
+}
+
+// DefaultHealthMonitor 返回全局存储健康检查，未启用时返回nil
+func DefaultHealthMonitor() *HealthMonitor {
+	defaultMonitorMu.RLock()
+	defer defaultMonitorMu.RUnlock()
+	return defaultMonitor
+}
@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// 存储相关错误
+var (
+	// ErrObjectNotFound 存储对象不存在
+	ErrObjectNotFound = errors.New("storage object not found")
+	// ErrInvalidPath 非法的存储路径
+	ErrInvalidPath = errors.New("invalid storage path")
+)
+
+// Storage 存储后端统一接口
+//
+// 所有路径均为相对于存储根(本地根目录或对象存储桶)的逻辑路径，使用"/"分隔。
+// 实现必须是并发安全的。
+//
+// 使用示例：
+//
+//	store, err := storage.NewFromConfig(config.AppConfig.Storage)
+//	w, err := store.Create(ctx, "files/2024/01/abc")
+//	_, err = io.Copy(w, reader)
+//	err = w.Close()
+type Storage interface {
+	// Type 返回存储类型(local/oss/s3/minio)
+	Type() string
+
+	// Put 将reader中的数据完整写入指定路径
+	Put(ctx context.Context, path string, reader io.Reader, size int64) error
+
+	// Create 打开一个流式写入器，Close成功后对象才可见；
+	// 写入失败时调用方应调用Abort放弃写入
+	Create(ctx context.Context, path string) (ObjectWriter, error)
+
+	// Open 打开对象用于读取
+	Open(ctx context.Context, path string) (io.ReadCloser, error)
+
+	// Stat 获取对象信息
+	Stat(ctx context.Context, path string) (*ObjectInfo, error)
+
+	// Exists 检查对象是否存在
+	Exists(ctx context.Context, path string) (bool, error)
+
+	// Delete 删除对象，对象不存在时不返回错误
+	Delete(ctx context.Context, path string) error
+}
+
+// ObjectWriter 流式对象写入器
+type ObjectWriter interface {
+	io.WriteCloser
+
+	// Abort 放弃写入并清理已写入的数据
+	Abort() error
+}
+
+// Composer 支持服务端拼接的存储后端(如S3/OSS的分片复制)
+//
+// 实现该接口的后端在合并分片时无需经过应用服务器中转数据
+type Composer interface {
+	Compose(ctx context.Context, dst string, sources []string) error
+}
+
+// URLPresigner 支持签发临时下载地址的存储后端
+//
+// 客户端可直接从存储服务下载，不经过应用服务器中转；本地存储不实现该接口
+type URLPresigner interface {
+	PresignURL(ctx context.Context, path string, expires time.Duration) (string, error)
+}
+
+// ObjectInfo 存储对象信息
+type ObjectInfo struct {
+	Path         string    // 对象路径
+	Size         int64     // 对象大小(字节)
+	LastModified time.Time // 最后修改时间
+	SHA256       string    // 内容SHA-256(十六进制)，存储后端未提供时为空
+
+	// 归档存储(仅对象存储)
+	StorageClass   string     // 存储类型，如 STANDARD、GLACIER，存储后端未提供时为空
+	RestoreOngoing bool       // 归档对象是否正在恢复
+	RestoredUntil  *time.Time // 归档对象恢复后可读取的截止时间
+}
+
+// IsNotFound 检查是否为对象不存在错误
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrObjectNotFound)
+}
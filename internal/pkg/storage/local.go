@@ -0,0 +1,225 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// StorageTypeLocal 本地存储类型标识
+const StorageTypeLocal = "local"
+
+// LocalStorage 本地文件系统存储
+type LocalStorage struct {
+	rootPath string
+}
+
+// NewLocalStorage 创建本地存储实例
+//
+// 根目录和写前日志目录不存在时会自动创建；上次崩溃遗留的未完成写入需在启动时调用 Recover 处理
+func NewLocalStorage(rootPath string) (*LocalStorage, error) {
+	if rootPath == "" {
+		return nil, fmt.Errorf("本地存储根目录不能为空")
+	}
+
+	absRoot, err := filepath.Abs(rootPath)
+	if err != nil {
+		return nil, fmt.Errorf("解析存储根目录失败: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Join(absRoot, journalDir), 0o750); err != nil {
+		return nil, fmt.Errorf("创建存储根目录失败: %w", err)
+	}
+
+	return &LocalStorage{rootPath: absRoot}, nil
+}
+
+// Type 返回存储类型
+func (s *LocalStorage) Type() string {
+	return StorageTypeLocal
+}
+
+// RootPath 返回存储根目录
+func (s *LocalStorage) RootPath() string {
+	return s.rootPath
+}
+
+// Put 写入完整对象
+func (s *LocalStorage) Put(ctx context.Context, path string, reader io.Reader, size int64) error {
+	writer, err := s.Create(ctx, path)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(writer, reader); err != nil {
+		_ = writer.Abort()
+		return fmt.Errorf("写入存储对象失败: %w", err)
+	}
+
+	return writer.Close()
+}
+
+// Create 打开流式写入器
+//
+// 数据先写入写前日志目录中的临时文件，Close时fsync后原子重命名到最终位置，
+// 崩溃时最终位置要么是完整的新对象，要么保持写入前的状态；Abort会删除临时文件
+func (s *LocalStorage) Create(ctx context.Context, path string) (ObjectWriter, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.resolve(path); err != nil {
+		return nil, err
+	}
+
+	entry := &journalEntry{
+		ID:        newJournalID(),
+		Path:      path,
+		State:     journalStateWriting,
+		Owner:     journalOwner,
+		StartedAt: time.Now(),
+	}
+	if err := s.writeEntry(entry, false); err != nil {
+		return nil, err
+	}
+
+	// #nosec G304 - 路径由日志ID生成，位于日志目录内
+	file, err := os.OpenFile(s.dataPath(entry.ID), os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o640)
+	if err != nil {
+		_ = s.removeJournal(entry.ID)
+		return nil, fmt.Errorf("创建存储对象失败: %w", err)
+	}
+
+	return &localObjectWriter{store: s, entry: entry, file: file}, nil
+}
+
+// Open 打开对象读取
+func (s *LocalStorage) Open(ctx context.Context, path string) (io.ReadCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	fullPath, err := s.resolve(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// #nosec G304 - 路径已经过resolve校验，限制在根目录内
+	file, err := os.Open(fullPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%s: %w", path, ErrObjectNotFound)
+		}
+		return nil, fmt.Errorf("打开存储对象失败: %w", err)
+	}
+
+	return file, nil
+}
+
+// Stat 获取对象信息
+func (s *LocalStorage) Stat(ctx context.Context, path string) (*ObjectInfo, error) {
+	fullPath, err := s.resolve(path)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%s: %w", path, ErrObjectNotFound)
+		}
+		return nil, fmt.Errorf("获取存储对象信息失败: %w", err)
+	}
+
+	return &ObjectInfo{
+		Path:         path,
+		Size:         info.Size(),
+		LastModified: info.ModTime(),
+	}, nil
+}
+
+// Exists 检查对象是否存在
+func (s *LocalStorage) Exists(ctx context.Context, path string) (bool, error) {
+	_, err := s.Stat(ctx, path)
+	if err == nil {
+		return true, nil
+	}
+	if IsNotFound(err) {
+		return false, nil
+	}
+	return false, err
+}
+
+// Delete 删除对象
+func (s *LocalStorage) Delete(ctx context.Context, path string) error {
+	fullPath, err := s.resolve(path)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(fullPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("删除存储对象失败: %w", err)
+	}
+	return nil
+}
+
+// resolve 将逻辑路径转换为根目录下的绝对路径，拒绝目录穿越
+func (s *LocalStorage) resolve(path string) (string, error) {
+	cleaned := filepath.Clean("/" + filepath.FromSlash(path))
+	if cleaned == string(filepath.Separator) || isJournalPath(cleaned) {
+		return "", fmt.Errorf("%q: %w", path, ErrInvalidPath)
+	}
+
+	fullPath := filepath.Join(s.rootPath, cleaned)
+	if !strings.HasPrefix(fullPath, s.rootPath+string(filepath.Separator)) {
+		return "", fmt.Errorf("%q: %w", path, ErrInvalidPath)
+	}
+	return fullPath, nil
+}
+
+// localObjectWriter 本地文件写入器，数据写入临时文件，Close时提交到最终位置
+type localObjectWriter struct {
+	store     *LocalStorage
+	entry     *journalEntry
+	file      *os.File
+	committed bool
+}
+
+// Write 写入数据
+func (w *localObjectWriter) Write(p []byte) (int, error) {
+	return w.file.Write(p)
+}
+
+// Close 同步临时文件并原子重命名到最终位置
+func (w *localObjectWriter) Close() error {
+	if err := w.file.Sync(); err != nil {
+		_ = w.file.Close()
+		return fmt.Errorf("同步存储对象失败: %w", err)
+	}
+	if err := w.file.Close(); err != nil {
+		return fmt.Errorf("关闭存储对象失败: %w", err)
+	}
+
+	w.entry.State = journalStateCommitted
+	if err := w.store.writeEntry(w.entry, true); err != nil {
+		return err
+	}
+	if err := w.store.commitEntry(w.entry); err != nil {
+		return err
+	}
+	w.committed = true
+	return nil
+}
+
+// Abort 关闭并删除临时文件，已提交的写入不受影响
+func (w *localObjectWriter) Abort() error {
+	if w.committed {
+		return nil
+	}
+	_ = w.file.Close()
+	return w.store.removeJournal(w.entry.ID)
+}
@@ -0,0 +1,254 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// journalDir 写前日志目录，位于存储根目录下，不能作为对象路径使用
+const journalDir = ".journal"
+
+// 日志记录状态
+const (
+	journalStateWriting   = "writing"   // 正在写入临时文件
+	journalStateCommitted = "committed" // 临时文件已同步到磁盘，等待重命名到最终位置
+)
+
+// journalOwner 当前进程的日志标识，恢复时跳过当前进程正在进行的写入
+var journalOwner = newJournalID()
+
+// journalEntry 写前日志记录
+//
+// 每次写入对应一条记录(<id>.json)和一个临时文件(<id>.data)。写入顺序：
+// 登记记录(writing) -> 写临时文件 -> fsync -> 记录改为committed并fsync -> 重命名到最终位置 -> fsync目录 -> 删除记录。
+// 崩溃后committed的写入数据已完整落盘，恢复时完成重命名；其余写入删除临时文件
+type journalEntry struct {
+	ID        string    `json:"id"`
+	Path      string    `json:"path"`  // 对象逻辑路径
+	State     string    `json:"state"` // writing/committed
+	Owner     string    `json:"owner"` // 发起写入的进程标识
+	StartedAt time.Time `json:"started_at"`
+}
+
+// RecoveryReport 本地存储崩溃恢复结果
+type RecoveryReport struct {
+	Completed []string // 数据已完整落盘、恢复时完成写入的对象路径
+	Discarded []string // 写入未完成、已删除临时数据的对象路径，对象保持写入前的状态
+	Orphans   int      // 没有日志记录(记录未落盘或已损坏)、已删除的临时文件数
+}
+
+// Repairs 返回修复的写入总数
+func (r *RecoveryReport) Repairs() int {
+	return len(r.Completed) + len(r.Discarded) + r.Orphans
+}
+
+// Recover 处理上次进程崩溃遗留的未完成写入
+//
+// 已提交的写入完成重命名，未提交的写入删除临时数据。必须在开始处理请求前调用，
+// 且存储根目录只能由一个服务进程使用；当前进程发起的写入会被跳过
+func (s *LocalStorage) Recover(ctx context.Context) (*RecoveryReport, error) {
+	items, err := os.ReadDir(s.journalPath())
+	if err != nil {
+		return nil, fmt.Errorf("读取写前日志失败: %w", err)
+	}
+
+	report := &RecoveryReport{}
+	entries := make(map[string]bool)
+	for _, item := range items {
+		id, ok := strings.CutSuffix(item.Name(), ".json")
+		if !ok {
+			continue
+		}
+		entries[id] = true
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		entry, err := s.readEntry(id)
+		if err != nil {
+			// 记录损坏说明写入尚未提交(提交时的记录经过fsync和原子替换)
+			if err := s.removeJournal(id); err != nil {
+				return report, err
+			}
+			report.Orphans++
+			continue
+		}
+		if entry.Owner == journalOwner {
+			continue
+		}
+
+		if entry.State == journalStateCommitted {
+			if err := s.commitEntry(entry); err != nil {
+				return report, err
+			}
+			report.Completed = append(report.Completed, entry.Path)
+			continue
+		}
+		if err := s.removeJournal(id); err != nil {
+			return report, err
+		}
+		report.Discarded = append(report.Discarded, entry.Path)
+	}
+
+	// 没有日志记录的临时文件和替换记录时遗留的临时记录
+	for _, item := range items {
+		name := item.Name()
+		if id, ok := strings.CutSuffix(name, ".data"); ok && !entries[id] {
+			if err := removeIfExists(filepath.Join(s.journalPath(), name)); err != nil {
+				return report, err
+			}
+			report.Orphans++
+		} else if strings.HasSuffix(name, ".json.tmp") {
+			if err := removeIfExists(filepath.Join(s.journalPath(), name)); err != nil {
+				return report, err
+			}
+		}
+	}
+	return report, nil
+}
+
+// journalPath 返回写前日志目录
+func (s *LocalStorage) journalPath() string {
+	return filepath.Join(s.rootPath, journalDir)
+}
+
+// dataPath 返回写入的临时文件路径
+func (s *LocalStorage) dataPath(id string) string {
+	return filepath.Join(s.journalPath(), id+".data")
+}
+
+// entryPath 返回日志记录路径
+func (s *LocalStorage) entryPath(id string) string {
+	return filepath.Join(s.journalPath(), id+".json")
+}
+
+// writeEntry 保存日志记录
+//
+// durable为true时先写临时记录并fsync，再原子替换并fsync目录，保证崩溃后读到完整的记录；
+// writing状态的记录丢失只会使临时文件被当作无记录的文件删除，不需要落盘
+func (s *LocalStorage) writeEntry(entry *journalEntry, durable bool) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("序列化写前日志失败: %w", err)
+	}
+	if !durable {
+		if err := os.WriteFile(s.entryPath(entry.ID), data, 0o640); err != nil {
+			return fmt.Errorf("写入写前日志失败: %w", err)
+		}
+		return nil
+	}
+
+	tmpPath := s.entryPath(entry.ID) + ".tmp"
+	// #nosec G304 - 路径由日志ID生成，位于日志目录内
+	file, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o640)
+	if err != nil {
+		return fmt.Errorf("写入写前日志失败: %w", err)
+	}
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("写入写前日志失败: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("同步写前日志失败: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("写入写前日志失败: %w", err)
+	}
+	if err := os.Rename(tmpPath, s.entryPath(entry.ID)); err != nil {
+		return fmt.Errorf("提交写前日志失败: %w", err)
+	}
+	return syncDir(s.journalPath())
+}
+
+// readEntry 读取日志记录
+func (s *LocalStorage) readEntry(id string) (*journalEntry, error) {
+	data, err := os.ReadFile(s.entryPath(id))
+	if err != nil {
+		return nil, err
+	}
+	var entry journalEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, err
+	}
+	if entry.ID != id || entry.Path == "" {
+		return nil, fmt.Errorf("写前日志记录无效: %s", id)
+	}
+	return &entry, nil
+}
+
+// commitEntry 将已提交写入的临时文件重命名到最终位置并删除日志记录
+//
+// 临时文件不存在说明重命名已在崩溃前完成，只需删除记录
+func (s *LocalStorage) commitEntry(entry *journalEntry) error {
+	fullPath, err := s.resolve(entry.Path)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0o750); err != nil {
+		return fmt.Errorf("创建存储目录失败: %w", err)
+	}
+	if err := os.Rename(s.dataPath(entry.ID), fullPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("提交存储对象失败: %w", err)
+	}
+	// 重命名落盘后才能删除记录，否则崩溃后既没有对象也没有可恢复的记录
+	if err := syncDir(filepath.Dir(fullPath)); err != nil {
+		return err
+	}
+	return removeIfExists(s.entryPath(entry.ID))
+}
+
+// removeJournal 删除写入的临时文件和日志记录
+//
+// 先删除临时文件，崩溃时残留的记录在恢复时按未完成写入处理
+func (s *LocalStorage) removeJournal(id string) error {
+	if err := removeIfExists(s.dataPath(id)); err != nil {
+		return err
+	}
+	return removeIfExists(s.entryPath(id))
+}
+
+// isJournalPath 检查逻辑路径是否指向写前日志目录
+func isJournalPath(cleaned string) bool {
+	sep := string(filepath.Separator)
+	return cleaned == sep+journalDir || strings.HasPrefix(cleaned, sep+journalDir+sep)
+}
+
+// newJournalID 生成随机日志ID
+func newJournalID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		// 系统随机数不可用时退化为时间戳，同一进程内仍然唯一
+		return fmt.Sprintf("%032x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(buf)
+}
+
+// syncDir 同步目录，使目录中的创建、重命名和删除落盘
+func syncDir(dir string) error {
+	// #nosec G304 - 目录位于存储根目录内
+	handle, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("同步存储目录失败: %w", err)
+	}
+	defer handle.Close()
+	if err := handle.Sync(); err != nil {
+		return fmt.Errorf("同步存储目录失败: %w", err)
+	}
+	return nil
+}
+
+// removeIfExists 删除文件，文件不存在时不返回错误
+func removeIfExists(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("删除写前日志失败: %w", err)
+	}
+	return nil
+}
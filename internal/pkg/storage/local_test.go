@@ -0,0 +1,188 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalStorage_Lifecycle(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	assert.Equal(t, StorageTypeLocal, store.Type())
+
+	require.NoError(t, store.Put(ctx, "a/b/c.txt", strings.NewReader("hello"), 5))
+
+	info, err := store.Stat(ctx, "a/b/c.txt")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), info.Size)
+
+	reader, err := store.Open(ctx, "a/b/c.txt")
+	require.NoError(t, err)
+	data, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	require.NoError(t, reader.Close())
+	assert.Equal(t, "hello", string(data))
+
+	require.NoError(t, store.Delete(ctx, "a/b/c.txt"))
+	exists, err := store.Exists(ctx, "a/b/c.txt")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	// 重复删除不报错
+	assert.NoError(t, store.Delete(ctx, "a/b/c.txt"))
+
+	_, err = store.Open(ctx, "a/b/c.txt")
+	assert.True(t, IsNotFound(err))
+}
+
+func TestLocalStorage_AbortRemovesPartialObject(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+
+	writer, err := store.Create(ctx, "partial.bin")
+	require.NoError(t, err)
+	_, err = writer.Write([]byte("partial"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Abort())
+
+	exists, err := store.Exists(ctx, "partial.bin")
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestLocalStorage_PathTraversal(t *testing.T) {
+	store, err := NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+
+	// 目录穿越会被限制在根目录内
+	full, err := store.resolve("../../etc/passwd")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(full, store.RootPath()))
+
+	_, err = store.resolve("/")
+	assert.ErrorIs(t, err, ErrInvalidPath)
+}
+
+// crashWriter 模拟写入过程中进程崩溃：临时文件保留，日志记录改为其他进程发起
+func crashWriter(t *testing.T, store *LocalStorage, path, data string, committed bool) {
+	t.Helper()
+	writer, err := store.Create(context.Background(), path)
+	require.NoError(t, err)
+	w := writer.(*localObjectWriter)
+	_, err = w.Write([]byte(data))
+	require.NoError(t, err)
+	require.NoError(t, w.file.Close())
+
+	w.entry.Owner = "crashed"
+	if committed {
+		w.entry.State = journalStateCommitted
+	}
+	require.NoError(t, store.writeEntry(w.entry, committed))
+}
+
+func readObject(t *testing.T, store *LocalStorage, path string) string {
+	t.Helper()
+	reader, err := store.Open(context.Background(), path)
+	require.NoError(t, err)
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestLocalStorage_WriteIsInvisibleUntilClose(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, store.Put(ctx, "a.txt", strings.NewReader("old"), 3))
+
+	writer, err := store.Create(ctx, "a.txt")
+	require.NoError(t, err)
+	_, err = writer.Write([]byte("new content"))
+	require.NoError(t, err)
+	assert.Equal(t, "old", readObject(t, store, "a.txt")) // 提交前仍是旧内容
+
+	// 放弃覆盖写入时保留原对象
+	require.NoError(t, writer.Abort())
+	assert.Equal(t, "old", readObject(t, store, "a.txt"))
+
+	require.NoError(t, store.Put(ctx, "a.txt", strings.NewReader("new"), 3))
+	assert.Equal(t, "new", readObject(t, store, "a.txt"))
+
+	items, err := os.ReadDir(store.journalPath())
+	require.NoError(t, err)
+	assert.Empty(t, items) // 提交和放弃后不残留日志
+
+	_, err = store.Create(ctx, ".journal/x")
+	assert.ErrorIs(t, err, ErrInvalidPath)
+}
+
+func TestLocalStorage_Recover(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, store.Put(ctx, "kept.txt", strings.NewReader("old"), 3))
+
+	crashWriter(t, store, "done/a.txt", "complete", true)
+	crashWriter(t, store, "kept.txt", "partial", false)
+	require.NoError(t, os.WriteFile(store.dataPath("orphan"), []byte("x"), 0o640))
+	require.NoError(t, os.WriteFile(store.entryPath("broken"), []byte("{"), 0o640))
+
+	// 当前进程正在进行的写入不受影响
+	inFlight, err := store.Create(ctx, "inflight.txt")
+	require.NoError(t, err)
+
+	report, err := store.Recover(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"done/a.txt"}, report.Completed)
+	assert.Equal(t, []string{"kept.txt"}, report.Discarded)
+	assert.Equal(t, 2, report.Orphans)
+	assert.Equal(t, 4, report.Repairs())
+
+	assert.Equal(t, "complete", readObject(t, store, "done/a.txt"))
+	assert.Equal(t, "old", readObject(t, store, "kept.txt"))
+
+	_, err = inFlight.Write([]byte("ok"))
+	require.NoError(t, err)
+	require.NoError(t, inFlight.Close())
+	assert.Equal(t, "ok", readObject(t, store, "inflight.txt"))
+
+	items, err := os.ReadDir(store.journalPath())
+	require.NoError(t, err)
+	assert.Empty(t, items)
+
+	// 重复恢复是空操作
+	report, err = store.Recover(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, report.Repairs())
+}
+
+func TestLocalStorage_RecoverAfterRename(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+
+	// 重命名完成但删除日志记录前崩溃
+	crashWriter(t, store, "b.txt", "renamed", true)
+	items, err := os.ReadDir(store.journalPath())
+	require.NoError(t, err)
+	for _, item := range items {
+		if id, ok := strings.CutSuffix(item.Name(), ".data"); ok {
+			require.NoError(t, os.Rename(store.dataPath(id), filepath.Join(store.RootPath(), "b.txt")))
+		}
+	}
+
+	report, err := store.Recover(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b.txt"}, report.Completed)
+	assert.Equal(t, "renamed", readObject(t, store, "b.txt"))
+}
@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// 归档相关常量
+const (
+	// MaxCopyObjectSize 单次CopyObject支持的最大对象大小(5GB)，更大的对象应使用存储桶生命周期规则归档
+	MaxCopyObjectSize = 5 * 1024 * 1024 * 1024
+
+	// 常用归档存储类型
+	StorageClassStandard    = "STANDARD"
+	StorageClassGlacier     = "GLACIER"
+	StorageClassDeepArchive = "DEEP_ARCHIVE"
+
+	// 恢复速度等级
+	RestoreTierExpedited = "Expedited"
+	RestoreTierStandard  = "Standard"
+	RestoreTierBulk      = "Bulk"
+)
+
+// S3Archiver 兼容S3协议的归档存储操作
+//
+// 通过原地复制修改对象的存储类型完成归档(S3 Glacier、OSS归档存储的S3兼容接口)，
+// 归档对象读取前需要先发起恢复，恢复完成后在指定天数内可读
+//
+// 使用示例：
+//
+//	archiver, err := storage.NewS3Archiver(storage.S3OptionsFromConfig(cfg.Storage.OSS), nil)
+//	err = archiver.Archive(ctx, key, storage.StorageClassGlacier)
+//	err = archiver.Restore(ctx, key, 7, storage.RestoreTierStandard)
+//	info, err := archiver.HeadObject(ctx, key) // info.RestoreOngoing / info.RestoredUntil
+type S3Archiver struct {
+	*s3Conn
+}
+
+// NewS3Archiver 创建归档存储操作，client为nil时使用带超时的默认客户端
+func NewS3Archiver(opts S3Options, client *http.Client) (*S3Archiver, error) {
+	conn, err := newS3Conn(opts, client)
+	if err != nil {
+		return nil, err
+	}
+	return &S3Archiver{s3Conn: conn}, nil
+}
+
+// Archive 将对象转换为指定的存储类型，元数据保持不变
+func (a *S3Archiver) Archive(ctx context.Context, key, storageClass string) error {
+	if key == "" || storageClass == "" {
+		return fmt.Errorf("对象键和存储类型不能为空")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, a.objectURL(key).String(), nil)
+	if err != nil {
+		return fmt.Errorf("创建归档请求失败: %w", err)
+	}
+	req.Header.Set("X-Amz-Copy-Source", (&url.URL{Path: "/" + a.opts.Bucket + "/" + key}).EscapedPath())
+	req.Header.Set("X-Amz-Metadata-Directive", "COPY")
+	req.Header.Set("X-Amz-Storage-Class", storageClass)
+
+	resp, err := a.do(req, emptyPayloadHash, http.StatusOK)
+	if err != nil {
+		return fmt.Errorf("归档对象失败: %w", err)
+	}
+	return resp.Body.Close()
+}
+
+// Restore 发起归档对象恢复，恢复后的副本保留days天
+//
+// 恢复是异步的，通过HeadObject轮询进度；恢复已在进行中时不返回错误
+func (a *S3Archiver) Restore(ctx context.Context, key string, days int, tier string) error {
+	if key == "" || days <= 0 {
+		return fmt.Errorf("对象键不能为空且恢复天数必须大于0")
+	}
+	if tier == "" {
+		tier = RestoreTierStandard
+	}
+
+	body := []byte(fmt.Sprintf(
+		"<RestoreRequest><Days>%d</Days><GlacierJobParameters><Tier>%s</Tier></GlacierJobParameters></RestoreRequest>",
+		days, tier))
+	restoreURL := a.objectURL(key)
+	restoreURL.RawQuery = "restore="
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, restoreURL.String(), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("创建恢复请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/xml")
+
+	// 202表示已发起恢复，200表示对象已恢复(本次请求延长了保留时间)
+	resp, err := a.do(req, hashHex(body), http.StatusAccepted, http.StatusOK)
+	if err != nil {
+		var s3Err *S3Error
+		if errors.As(err, &s3Err) && s3Err.Code == "RestoreAlreadyInProgress" {
+			return nil
+		}
+		return fmt.Errorf("发起归档恢复失败: %w", err)
+	}
+	return resp.Body.Close()
+}
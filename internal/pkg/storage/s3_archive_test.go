@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestArchiver(t *testing.T, handler http.HandlerFunc) *S3Archiver {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	archiver, err := NewS3Archiver(S3Options{
+		Endpoint:        server.URL,
+		Bucket:          "files",
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "SECRET",
+		PathStyle:       true,
+	}, nil)
+	require.NoError(t, err)
+	return archiver
+}
+
+func TestS3Archiver_Archive(t *testing.T) {
+	archiver := newTestArchiver(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPut, r.Method)
+		assert.Equal(t, "/files/direct/1/abc", r.URL.Path)
+		assert.Equal(t, "/files/direct/1/abc", r.Header.Get("X-Amz-Copy-Source"))
+		assert.Equal(t, "COPY", r.Header.Get("X-Amz-Metadata-Directive"))
+		assert.Equal(t, StorageClassGlacier, r.Header.Get("X-Amz-Storage-Class"))
+		assert.Contains(t, r.Header.Get("Authorization"), "x-amz-storage-class")
+		w.WriteHeader(http.StatusOK)
+	})
+
+	require.NoError(t, archiver.Archive(context.Background(), "direct/1/abc", StorageClassGlacier))
+}
+
+func TestS3Archiver_Restore(t *testing.T) {
+	t.Run("initiated", func(t *testing.T) {
+		archiver := newTestArchiver(t, func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodPost, r.Method)
+			_, ok := r.URL.Query()["restore"]
+			assert.True(t, ok)
+			body, _ := io.ReadAll(r.Body)
+			assert.Contains(t, string(body), "<Days>7</Days>")
+			assert.Contains(t, string(body), "<Tier>Bulk</Tier>")
+			w.WriteHeader(http.StatusAccepted)
+		})
+
+		require.NoError(t, archiver.Restore(context.Background(), "k", 7, RestoreTierBulk))
+	})
+
+	t.Run("already in progress", func(t *testing.T) {
+		archiver := newTestArchiver(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusConflict)
+			_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?><Error><Code>RestoreAlreadyInProgress</Code></Error>`))
+		})
+
+		assert.NoError(t, archiver.Restore(context.Background(), "k", 7, ""))
+	})
+
+	t.Run("not archived", func(t *testing.T) {
+		archiver := newTestArchiver(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`<Error><Code>InvalidObjectState</Code></Error>`))
+		})
+
+		err := archiver.Restore(context.Background(), "k", 7, "")
+		var s3Err *S3Error
+		require.ErrorAs(t, err, &s3Err)
+		assert.Equal(t, "InvalidObjectState", s3Err.Code)
+	})
+}
+
+func TestS3Archiver_HeadObjectRestoreState(t *testing.T) {
+	var restoreHeader string
+	archiver := newTestArchiver(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "10")
+		w.Header().Set("X-Amz-Storage-Class", StorageClassGlacier)
+		if restoreHeader != "" {
+			w.Header().Set("X-Amz-Restore", restoreHeader)
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	ctx := context.Background()
+
+	info, err := archiver.HeadObject(ctx, "k")
+	require.NoError(t, err)
+	assert.Equal(t, StorageClassGlacier, info.StorageClass)
+	assert.False(t, info.RestoreOngoing)
+	assert.Nil(t, info.RestoredUntil)
+
+	restoreHeader = `ongoing-request="true"`
+	info, err = archiver.HeadObject(ctx, "k")
+	require.NoError(t, err)
+	assert.True(t, info.RestoreOngoing)
+
+	restoreHeader = `ongoing-request="false", expiry-date="Fri, 21 Dec 2012 00:00:00 GMT"`
+	info, err = archiver.HeadObject(ctx, "k")
+	require.NoError(t, err)
+	assert.False(t, info.RestoreOngoing)
+	require.NotNil(t, info.RestoredUntil)
+	assert.True(t, info.RestoredUntil.Equal(time.Date(2012, 12, 21, 0, 0, 0, 0, time.UTC)))
+}
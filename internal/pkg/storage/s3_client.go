@@ -0,0 +1,220 @@
+package storage
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloudpan/internal/pkg/config"
+)
+
+// 对象存储类型标识
+const (
+	StorageTypeOSS   = "oss"
+	StorageTypeS3    = "s3"
+	StorageTypeMinIO = "minio"
+)
+
+// S3Options 兼容S3协议的对象存储连接选项
+type S3Options struct {
+	Endpoint        string // 服务地址，如 s3.amazonaws.com、oss-cn-hangzhou.aliyuncs.com、minio.local:9000
+	Bucket          string // 存储桶名称
+	AccessKeyID     string // 访问密钥ID
+	SecretAccessKey string // 访问密钥
+	Region          string // 区域
+	Secure          bool   // 是否使用HTTPS
+	PathStyle       bool   // 是否使用路径风格(endpoint/bucket/key)，MinIO等自建服务通常需要开启
+}
+
+// S3OptionsFromConfig 从OSS配置生成连接选项
+func S3OptionsFromConfig(cfg config.OSSStorageConfig) S3Options {
+	return S3Options{
+		Endpoint:        cfg.Endpoint,
+		Bucket:          cfg.BucketName,
+		AccessKeyID:     cfg.AccessKeyID,
+		SecretAccessKey: cfg.AccessKeySecret,
+		Region:          cfg.Region,
+		Secure:          cfg.Secure,
+		// MinIO等自建服务通常没有泛域名解析，使用路径风格访问存储桶
+		PathStyle: cfg.Provider == "minio",
+	}
+}
+
+// S3OptionsFromStorageConfig 从S3存储后端配置生成连接选项
+func S3OptionsFromStorageConfig(cfg config.S3StorageConfig) S3Options {
+	return S3Options{
+		Endpoint:        cfg.Endpoint,
+		Bucket:          cfg.Bucket,
+		AccessKeyID:     cfg.AccessKeyID,
+		SecretAccessKey: cfg.SecretAccessKey,
+		Region:          cfg.Region,
+		Secure:          cfg.Secure,
+		PathStyle:       cfg.PathStyle,
+	}
+}
+
+// StorageTypeForProvider 返回OSS服务商对应的文件存储类型
+func StorageTypeForProvider(provider string) string {
+	switch provider {
+	case "aws":
+		return StorageTypeS3
+	case "minio":
+		return StorageTypeMinIO
+	default:
+		return StorageTypeOSS
+	}
+}
+
+// restoreHeaderPattern 解析 x-amz-restore 响应头
+var restoreHeaderPattern = regexp.MustCompile(`ongoing-request="(true|false)"(?:,\s*expiry-date="([^"]+)")?`)
+
+// s3Conn S3协议连接，负责地址拼装、请求签名和发送，供预签名器和归档器共用
+type s3Conn struct {
+	opts   S3Options
+	signer *SigV4Signer
+	client *http.Client
+	now    func() time.Time
+}
+
+// newS3Conn 创建S3连接，client为nil时使用带超时的默认客户端
+func newS3Conn(opts S3Options, client *http.Client) (*s3Conn, error) {
+	opts.Endpoint = strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(opts.Endpoint, "https://"), "http://"), "/")
+	if opts.Endpoint == "" {
+		return nil, fmt.Errorf("对象存储地址不能为空")
+	}
+	if opts.Bucket == "" {
+		return nil, fmt.Errorf("存储桶名称不能为空")
+	}
+	if opts.AccessKeyID == "" || opts.SecretAccessKey == "" {
+		return nil, fmt.Errorf("对象存储访问密钥不能为空")
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &s3Conn{
+		opts:   opts,
+		signer: NewSigV4Signer(opts.AccessKeyID, opts.SecretAccessKey, opts.Region),
+		client: client,
+		now:    time.Now,
+	}, nil
+}
+
+// Bucket 返回存储桶名称
+func (c *s3Conn) Bucket() string {
+	return c.opts.Bucket
+}
+
+// HeadObject 查询对象信息
+//
+// 存储服务返回SHA-256校验和、存储类型和归档恢复状态时一并带回
+func (c *s3Conn) HeadObject(ctx context.Context, key string) (*ObjectInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, c.objectURL(key).String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建对象查询请求失败: %w", err)
+	}
+	req.Header.Set("X-Amz-Checksum-Mode", "ENABLED")
+
+	resp, err := c.do(req, emptyPayloadHash, http.StatusOK)
+	if err != nil {
+		if IsNotFound(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("查询存储对象失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	size, err := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("解析对象大小失败: %w", err)
+	}
+	info := &ObjectInfo{
+		Path:         key,
+		Size:         size,
+		StorageClass: resp.Header.Get("X-Amz-Storage-Class"),
+	}
+	if modified, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		info.LastModified = modified
+	}
+	if checksum := resp.Header.Get("X-Amz-Checksum-Sha256"); checksum != "" {
+		if raw, err := base64.StdEncoding.DecodeString(checksum); err == nil {
+			info.SHA256 = hex.EncodeToString(raw)
+		}
+	}
+	if match := restoreHeaderPattern.FindStringSubmatch(resp.Header.Get("X-Amz-Restore")); match != nil {
+		info.RestoreOngoing = match[1] == "true"
+		if expiry, err := http.ParseTime(match[2]); err == nil {
+			info.RestoredUntil = &expiry
+		}
+	}
+	return info, nil
+}
+
+// do 签名并发送请求，返回非预期状态码时读取错误码组成错误
+func (c *s3Conn) do(req *http.Request, payloadHash string, expected ...int) (*http.Response, error) {
+	c.signer.SignRequest(req, payloadHash, c.now())
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	for _, status := range expected {
+		if resp.StatusCode == status {
+			return resp, nil
+		}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrObjectNotFound
+	}
+	return nil, &S3Error{StatusCode: resp.StatusCode, Code: readS3ErrorCode(resp)}
+}
+
+// bucketURL 返回存储桶访问地址
+func (c *s3Conn) bucketURL() *url.URL {
+	scheme := "http"
+	if c.opts.Secure {
+		scheme = "https"
+	}
+	if c.opts.PathStyle {
+		return &url.URL{Scheme: scheme, Host: c.opts.Endpoint, Path: "/" + c.opts.Bucket + "/"}
+	}
+	return &url.URL{Scheme: scheme, Host: c.opts.Bucket + "." + c.opts.Endpoint, Path: "/"}
+}
+
+// objectURL 返回对象访问地址
+func (c *s3Conn) objectURL(key string) *url.URL {
+	objectURL := c.bucketURL()
+	objectURL.Path = strings.TrimSuffix(objectURL.Path, "/") + "/" + strings.TrimPrefix(key, "/")
+	return objectURL
+}
+
+// S3Error 对象存储返回的错误
+type S3Error struct {
+	StatusCode int    // HTTP状态码
+	Code       string // S3错误码，如 RestoreAlreadyInProgress
+}
+
+// Error 实现error接口
+func (e *S3Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("对象存储请求失败: HTTP %d", e.StatusCode)
+	}
+	return fmt.Sprintf("对象存储请求失败: HTTP %d %s", e.StatusCode, e.Code)
+}
+
+// readS3ErrorCode 从XML错误响应中读取错误码
+func readS3ErrorCode(resp *http.Response) string {
+	var body struct {
+		Code string `xml:"Code"`
+	}
+	_ = xml.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&body)
+	return body.Code
+}
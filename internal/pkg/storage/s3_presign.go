@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// 预签名相关限制
+const (
+	// MaxPresignExpiry 预签名凭证最长有效期，S3限制为7天
+	MaxPresignExpiry = 7 * 24 * time.Hour
+	// DefaultPresignExpiry 默认预签名凭证有效期
+	DefaultPresignExpiry = 15 * time.Minute
+)
+
+// PostPolicy 浏览器直传的上传约束，签名后浏览器只能上传完全符合约束的对象
+type PostPolicy struct {
+	Key         string        // 对象键
+	ContentType string        // 内容类型
+	Size        int64         // 文件大小(字节)，上传大小必须完全一致
+	SHA256      string        // 文件SHA-256(十六进制)，为空时不校验内容
+	Expires     time.Duration // 凭证有效期
+}
+
+// PresignedPost 预签名POST表单，浏览器以multipart/form-data提交到URL，文件字段必须放在最后
+type PresignedPost struct {
+	URL       string            `json:"url"`        // 表单提交地址
+	Fields    map[string]string `json:"fields"`     // 需原样提交的表单字段
+	ExpiresAt time.Time         `json:"expires_at"` // 凭证过期时间
+}
+
+// S3Presigner 兼容S3协议的对象存储预签名器
+//
+// 为浏览器签发只能上传指定对象的POST表单凭证，并在上传完成后通过HEAD请求核对对象，
+// 访问密钥始终保留在服务端
+//
+// 使用示例：
+//
+//	presigner, err := storage.NewS3Presigner(storage.S3OptionsFromConfig(cfg.Storage.OSS), nil)
+//	post, err := presigner.PresignPost(storage.PostPolicy{Key: key, Size: size, SHA256: hash})
+//	info, err := presigner.HeadObject(ctx, key)
+type S3Presigner struct {
+	*s3Conn
+}
+
+// NewS3Presigner 创建S3预签名器，client为nil时使用带超时的默认客户端
+func NewS3Presigner(opts S3Options, client *http.Client) (*S3Presigner, error) {
+	conn, err := newS3Conn(opts, client)
+	if err != nil {
+		return nil, err
+	}
+	return &S3Presigner{s3Conn: conn}, nil
+}
+
+// PresignPost 签发浏览器直传的POST表单
+//
+// 策略同时绑定对象键、内容类型和精确的文件大小；提供SHA256时存储服务会校验上传内容，
+// 浏览器无法借此凭证上传其他对象或篡改文件
+func (p *S3Presigner) PresignPost(policy PostPolicy) (*PresignedPost, error) {
+	if policy.Key == "" {
+		return nil, fmt.Errorf("对象键不能为空")
+	}
+	if policy.Size <= 0 {
+		return nil, fmt.Errorf("文件大小必须大于0")
+	}
+	expires := policy.Expires
+	if expires <= 0 {
+		expires = DefaultPresignExpiry
+	}
+	if expires > MaxPresignExpiry {
+		expires = MaxPresignExpiry
+	}
+	contentType := policy.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	now := p.now().UTC()
+	expiresAt := now.Add(expires)
+	fields := map[string]string{
+		"key":                   policy.Key,
+		"Content-Type":          contentType,
+		"success_action_status": "201",
+		"x-amz-algorithm":       sigV4Algorithm,
+		"x-amz-credential":      p.signer.Credential(now),
+		"x-amz-date":            now.Format(sigV4TimeFormat),
+	}
+	if policy.SHA256 != "" {
+		checksum, err := hexToBase64(policy.SHA256)
+		if err != nil {
+			return nil, err
+		}
+		fields["x-amz-checksum-algorithm"] = "SHA256"
+		fields["x-amz-checksum-sha256"] = checksum
+	}
+
+	conditions := []interface{}{
+		map[string]string{"bucket": p.opts.Bucket},
+		[]interface{}{"content-length-range", policy.Size, policy.Size},
+	}
+	for name, value := range fields {
+		conditions = append(conditions, map[string]string{name: value})
+	}
+	document, err := json.Marshal(map[string]interface{}{
+		"expiration": expiresAt.Format("2006-01-02T15:04:05.000Z"),
+		"conditions": conditions,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("生成上传策略失败: %w", err)
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(document)
+	fields["policy"] = encoded
+	fields["x-amz-signature"] = p.signer.SignPolicy(encoded, now)
+
+	return &PresignedPost{
+		URL:       p.bucketURL().String(),
+		Fields:    fields,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// hexToBase64 将十六进制SHA-256转换为S3校验和使用的base64格式
+func hexToBase64(value string) (string, error) {
+	raw, err := hex.DecodeString(value)
+	if err != nil || len(raw) != 32 {
+		return "", errors.New("SHA-256必须是64位十六进制字符串")
+	}
+	return base64.StdEncoding.EncodeToString(raw), nil
+}
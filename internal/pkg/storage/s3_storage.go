@@ -0,0 +1,561 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 分片上传限制
+const (
+	// MinMultipartPartSize S3要求除最后一片外每片至少5MB
+	MinMultipartPartSize = 5 * 1024 * 1024
+	// DefaultMultipartPartSize 默认分片大小，最多10000片时单个对象上限约160GB
+	DefaultMultipartPartSize = 16 * 1024 * 1024
+	// MaxMultipartParts 单个分片上传最多的分片数
+	MaxMultipartParts = 10000
+)
+
+// S3Storage 兼容S3协议的文件存储后端(AWS S3、MinIO等)
+//
+// 写入时先在内存中缓冲一个分片：对象不超过分片大小时使用单次PutObject上传，
+// 超过后自动转为分片上传，内存占用始终不超过一个分片；Close成功后对象才可见，
+// Abort会取消分片上传并清理已上传的分片
+//
+// 使用示例：
+//
+//	store, err := storage.NewS3Storage(storage.S3OptionsFromStorageConfig(cfg.Storage.S3), cfg.Storage.S3.PartSize, nil)
+//	w, err := store.Create(ctx, "files/2024/01/abc")
+//	_, err = io.Copy(w, reader)
+//	err = w.Close()
+//	link, err := store.PresignURL(ctx, "files/2024/01/abc", 15*time.Minute)
+type S3Storage struct {
+	*s3Conn
+	partSize int64
+}
+
+// NewS3Storage 创建S3存储，partSize为0时使用默认分片大小，client为nil时使用默认客户端
+//
+// 对象存储的读写可能持续较长时间，默认客户端不设置整体超时，由调用方的context控制
+func NewS3Storage(opts S3Options, partSize int64, client *http.Client) (*S3Storage, error) {
+	if partSize == 0 {
+		partSize = DefaultMultipartPartSize
+	}
+	if partSize < MinMultipartPartSize {
+		return nil, fmt.Errorf("分片大小不能小于 %d 字节", MinMultipartPartSize)
+	}
+	if client == nil {
+		client = &http.Client{}
+	}
+	conn, err := newS3Conn(opts, client)
+	if err != nil {
+		return nil, err
+	}
+	return &S3Storage{s3Conn: conn, partSize: partSize}, nil
+}
+
+// Type 返回存储类型
+func (s *S3Storage) Type() string {
+	return StorageTypeS3
+}
+
+// Put 写入完整对象
+func (s *S3Storage) Put(ctx context.Context, path string, reader io.Reader, size int64) error {
+	writer, err := s.Create(ctx, path)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(writer, reader); err != nil {
+		_ = writer.Abort()
+		return fmt.Errorf("写入存储对象失败: %w", err)
+	}
+	return writer.Close()
+}
+
+// Create 打开流式写入器
+func (s *S3Storage) Create(ctx context.Context, path string) (ObjectWriter, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	key, err := objectKey(path)
+	if err != nil {
+		return nil, err
+	}
+	return &s3ObjectWriter{storage: s, ctx: ctx, key: key}, nil
+}
+
+// Open 打开对象读取
+func (s *S3Storage) Open(ctx context.Context, path string) (io.ReadCloser, error) {
+	key, err := objectKey(path)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.objectURL(key).String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建对象读取请求失败: %w", err)
+	}
+	resp, err := s.do(req, emptyPayloadHash, http.StatusOK)
+	if err != nil {
+		if IsNotFound(err) {
+			return nil, fmt.Errorf("%s: %w", path, ErrObjectNotFound)
+		}
+		return nil, fmt.Errorf("打开存储对象失败: %w", err)
+	}
+	return resp.Body, nil
+}
+
+// OpenRange 读取对象从offset开始的length个字节
+func (s *S3Storage) OpenRange(ctx context.Context, path string, offset, length int64) (io.ReadCloser, error) {
+	if offset < 0 || length <= 0 {
+		return nil, fmt.Errorf("无效的读取范围: offset=%d, length=%d", offset, length)
+	}
+	key, err := objectKey(path)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.objectURL(key).String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建对象读取请求失败: %w", err)
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+
+	resp, err := s.do(req, emptyPayloadHash, http.StatusPartialContent, http.StatusOK)
+	if err != nil {
+		if IsNotFound(err) {
+			return nil, fmt.Errorf("%s: %w", path, ErrObjectNotFound)
+		}
+		return nil, fmt.Errorf("打开存储对象失败: %w", err)
+	}
+	if resp.StatusCode == http.StatusOK && offset > 0 {
+		// 存储服务忽略了Range请求头，返回的是完整对象
+		resp.Body.Close()
+		return nil, fmt.Errorf("存储服务不支持范围读取")
+	}
+	return resp.Body, nil
+}
+
+// Stat 获取对象信息
+func (s *S3Storage) Stat(ctx context.Context, path string) (*ObjectInfo, error) {
+	key, err := objectKey(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := s.HeadObject(ctx, key)
+	if err != nil {
+		if IsNotFound(err) {
+			return nil, fmt.Errorf("%s: %w", path, ErrObjectNotFound)
+		}
+		return nil, err
+	}
+	info.Path = path
+	return info, nil
+}
+
+// Exists 检查对象是否存在
+func (s *S3Storage) Exists(ctx context.Context, path string) (bool, error) {
+	_, err := s.Stat(ctx, path)
+	if err == nil {
+		return true, nil
+	}
+	if IsNotFound(err) {
+		return false, nil
+	}
+	return false, err
+}
+
+// Delete 删除对象
+func (s *S3Storage) Delete(ctx context.Context, path string) error {
+	key, err := objectKey(path)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, s.objectURL(key).String(), nil)
+	if err != nil {
+		return fmt.Errorf("创建对象删除请求失败: %w", err)
+	}
+	resp, err := s.do(req, emptyPayloadHash, http.StatusNoContent, http.StatusOK)
+	if err != nil {
+		if IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("删除存储对象失败: %w", err)
+	}
+	return resp.Body.Close()
+}
+
+// PresignURL 签发临时下载地址
+func (s *S3Storage) PresignURL(ctx context.Context, path string, expires time.Duration) (string, error) {
+	key, err := objectKey(path)
+	if err != nil {
+		return "", err
+	}
+	if expires <= 0 {
+		expires = DefaultPresignExpiry
+	}
+	if expires > MaxPresignExpiry {
+		expires = MaxPresignExpiry
+	}
+	return s.signer.PresignURL(http.MethodGet, s.objectURL(key), expires, s.now()), nil
+}
+
+// Compose 按顺序拼接多个对象，数据在存储服务内部复制
+//
+// 使用分片上传的UploadPartCopy，除最后一个外每个源对象必须不小于5MB，
+// 不满足时退回到经由应用服务器的流式复制
+func (s *S3Storage) Compose(ctx context.Context, dst string, sources []string) error {
+	if len(sources) == 0 {
+		return fmt.Errorf("拼接的源对象不能为空")
+	}
+	if len(sources) > MaxMultipartParts {
+		return fmt.Errorf("拼接的源对象不能超过 %d 个", MaxMultipartParts)
+	}
+	dstKey, err := objectKey(dst)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, len(sources))
+	copyable := true
+	for i, source := range sources {
+		if keys[i], err = objectKey(source); err != nil {
+			return err
+		}
+		if i == len(sources)-1 {
+			continue
+		}
+		info, err := s.Stat(ctx, source)
+		if err != nil {
+			return err
+		}
+		if info.Size < MinMultipartPartSize {
+			copyable = false
+		}
+	}
+	if !copyable {
+		return s.composeByStreaming(ctx, dst, sources)
+	}
+
+	upload, err := s.createMultipartUpload(ctx, dstKey)
+	if err != nil {
+		return err
+	}
+	parts := make([]completedPart, 0, len(keys))
+	for i, key := range keys {
+		etag, err := s.uploadPartCopy(ctx, dstKey, upload, i+1, key)
+		if err != nil {
+			_ = s.abortMultipartUpload(context.WithoutCancel(ctx), dstKey, upload)
+			return err
+		}
+		parts = append(parts, completedPart{PartNumber: i + 1, ETag: etag})
+	}
+	if err := s.completeMultipartUpload(ctx, dstKey, upload, parts); err != nil {
+		_ = s.abortMultipartUpload(context.WithoutCancel(ctx), dstKey, upload)
+		return err
+	}
+	return nil
+}
+
+// composeByStreaming 依次读取源对象写入目标
+func (s *S3Storage) composeByStreaming(ctx context.Context, dst string, sources []string) error {
+	writer, err := s.Create(ctx, dst)
+	if err != nil {
+		return err
+	}
+	for _, source := range sources {
+		reader, err := s.Open(ctx, source)
+		if err != nil {
+			_ = writer.Abort()
+			return err
+		}
+		_, err = io.Copy(writer, reader)
+		reader.Close()
+		if err != nil {
+			_ = writer.Abort()
+			return fmt.Errorf("复制存储对象失败: %w", err)
+		}
+	}
+	return writer.Close()
+}
+
+// putObject 单次上传完整对象
+func (s *S3Storage) putObject(ctx context.Context, key string, data []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.objectURL(key).String(), bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("创建上传请求失败: %w", err)
+	}
+	resp, err := s.do(req, hashHex(data), http.StatusOK)
+	if err != nil {
+		return fmt.Errorf("上传存储对象失败: %w", err)
+	}
+	return resp.Body.Close()
+}
+
+// createMultipartUpload 发起分片上传，返回上传ID
+func (s *S3Storage) createMultipartUpload(ctx context.Context, key string) (string, error) {
+	u := s.objectURL(key)
+	u.RawQuery = "uploads="
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("创建分片上传请求失败: %w", err)
+	}
+	resp, err := s.do(req, emptyPayloadHash, http.StatusOK)
+	if err != nil {
+		return "", fmt.Errorf("发起分片上传失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := xml.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&result); err != nil || result.UploadID == "" {
+		return "", fmt.Errorf("解析分片上传ID失败: %v", err)
+	}
+	return result.UploadID, nil
+}
+
+// uploadPart 上传一个分片，返回ETag
+func (s *S3Storage) uploadPart(ctx context.Context, key, uploadID string, number int, data []byte) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.partURL(key, uploadID, number), bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("创建分片上传请求失败: %w", err)
+	}
+	resp, err := s.do(req, hashHex(data), http.StatusOK)
+	if err != nil {
+		return "", fmt.Errorf("上传第 %d 个分片失败: %w", number, err)
+	}
+	resp.Body.Close()
+	return resp.Header.Get("ETag"), nil
+}
+
+// uploadPartCopy 将已有对象复制为一个分片，返回ETag
+func (s *S3Storage) uploadPartCopy(ctx context.Context, key, uploadID string, number int, source string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.partURL(key, uploadID, number), nil)
+	if err != nil {
+		return "", fmt.Errorf("创建分片复制请求失败: %w", err)
+	}
+	req.Header.Set("X-Amz-Copy-Source", (&url.URL{Path: "/" + s.opts.Bucket + "/" + source}).EscapedPath())
+
+	resp, err := s.do(req, emptyPayloadHash, http.StatusOK)
+	if err != nil {
+		return "", fmt.Errorf("复制第 %d 个分片失败: %w", number, err)
+	}
+	defer resp.Body.Close()
+
+	// 复制失败时存储服务可能返回200并在响应体中给出错误
+	var result struct {
+		XMLName xml.Name
+		ETag    string `xml:"ETag"`
+		Code    string `xml:"Code"`
+	}
+	if err := xml.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&result); err != nil {
+		return "", fmt.Errorf("解析分片复制结果失败: %w", err)
+	}
+	if result.XMLName.Local == "Error" {
+		return "", fmt.Errorf("复制第 %d 个分片失败: %w", number, &S3Error{StatusCode: resp.StatusCode, Code: result.Code})
+	}
+	return result.ETag, nil
+}
+
+// completedPart 已上传的分片
+type completedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+// completeMultipartUpload 完成分片上传
+func (s *S3Storage) completeMultipartUpload(ctx context.Context, key, uploadID string, parts []completedPart) error {
+	body, err := xml.Marshal(struct {
+		XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+		Parts   []completedPart `xml:"Part"`
+	}{Parts: parts})
+	if err != nil {
+		return fmt.Errorf("生成分片列表失败: %w", err)
+	}
+
+	u := s.objectURL(key)
+	u.RawQuery = url.Values{"uploadId": {uploadID}}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("创建完成分片上传请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/xml")
+
+	resp, err := s.do(req, hashHex(body), http.StatusOK)
+	if err != nil {
+		return fmt.Errorf("完成分片上传失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// 合并失败时存储服务可能返回200并在响应体中给出错误
+	var result struct {
+		XMLName xml.Name
+		Code    string `xml:"Code"`
+	}
+	if err := xml.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&result); err == nil && result.XMLName.Local == "Error" {
+		return fmt.Errorf("完成分片上传失败: %w", &S3Error{StatusCode: resp.StatusCode, Code: result.Code})
+	}
+	return nil
+}
+
+// abortMultipartUpload 取消分片上传并清理已上传的分片
+func (s *S3Storage) abortMultipartUpload(ctx context.Context, key, uploadID string) error {
+	u := s.objectURL(key)
+	u.RawQuery = url.Values{"uploadId": {uploadID}}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, u.String(), nil)
+	if err != nil {
+		return fmt.Errorf("创建取消分片上传请求失败: %w", err)
+	}
+	resp, err := s.do(req, emptyPayloadHash, http.StatusNoContent, http.StatusOK)
+	if err != nil {
+		if IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("取消分片上传失败: %w", err)
+	}
+	return resp.Body.Close()
+}
+
+// partURL 返回分片上传地址
+func (s *S3Storage) partURL(key, uploadID string, number int) string {
+	u := s.objectURL(key)
+	u.RawQuery = url.Values{"partNumber": {strconv.Itoa(number)}, "uploadId": {uploadID}}.Encode()
+	return u.String()
+}
+
+// objectKey 将逻辑路径规范化为对象键，拒绝目录穿越
+func objectKey(p string) (string, error) {
+	cleaned := path.Clean("/" + p)
+	if cleaned == "/" || strings.Contains(p, "\\") {
+		return "", fmt.Errorf("%q: %w", p, ErrInvalidPath)
+	}
+	return strings.TrimPrefix(cleaned, "/"), nil
+}
+
+// s3ObjectWriter S3流式写入器
+type s3ObjectWriter struct {
+	storage  *S3Storage
+	ctx      context.Context
+	key      string
+	buf      []byte
+	uploadID string
+	parts    []completedPart
+	err      error
+	done     bool
+}
+
+// Write 缓冲数据，缓冲满一个分片时上传
+func (w *s3ObjectWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	if w.done {
+		return 0, fmt.Errorf("写入器已关闭")
+	}
+
+	written := 0
+	for len(p) > 0 {
+		if w.buf == nil {
+			w.buf = make([]byte, 0, w.storage.partSize)
+		}
+		n := min(len(p), cap(w.buf)-len(w.buf))
+		w.buf = append(w.buf, p[:n]...)
+		p = p[n:]
+		written += n
+
+		if len(w.buf) == cap(w.buf) {
+			if err := w.flushPart(); err != nil {
+				w.err = err
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+// flushPart 上传缓冲中的数据作为下一个分片
+func (w *s3ObjectWriter) flushPart() error {
+	if len(w.parts) >= MaxMultipartParts {
+		return fmt.Errorf("对象超过分片上传的最大分片数 %d", MaxMultipartParts)
+	}
+	if w.uploadID == "" {
+		uploadID, err := w.storage.createMultipartUpload(w.ctx, w.key)
+		if err != nil {
+			return err
+		}
+		w.uploadID = uploadID
+	}
+
+	number := len(w.parts) + 1
+	etag, err := w.storage.uploadPart(w.ctx, w.key, w.uploadID, number, w.buf)
+	if err != nil {
+		return err
+	}
+	w.parts = append(w.parts, completedPart{PartNumber: number, ETag: etag})
+	w.buf = w.buf[:0]
+	return nil
+}
+
+// Close 上传剩余数据并完成写入，失败时自动放弃已上传的分片
+func (w *s3ObjectWriter) Close() error {
+	if w.done {
+		return w.err
+	}
+	if w.err != nil {
+		_ = w.Abort()
+		return w.err
+	}
+	w.done = true
+
+	if w.uploadID == "" {
+		w.err = w.storage.putObject(w.ctx, w.key, w.buf)
+		w.buf = nil
+		return w.err
+	}
+
+	if len(w.buf) > 0 {
+		if err := w.flushPart(); err != nil {
+			w.err = err
+			_ = w.abortUpload()
+			return err
+		}
+	}
+	if err := w.storage.completeMultipartUpload(w.ctx, w.key, w.uploadID, w.parts); err != nil {
+		w.err = err
+		_ = w.abortUpload()
+		return err
+	}
+	w.buf = nil
+	return nil
+}
+
+// Abort 放弃写入，已发起的分片上传会被取消
+func (w *s3ObjectWriter) Abort() error {
+	w.done = true
+	w.buf = nil
+	if w.err == nil {
+		w.err = fmt.Errorf("写入已放弃")
+	}
+	return w.abortUpload()
+}
+
+// abortUpload 取消分片上传，请求上下文已取消时仍需完成清理
+func (w *s3ObjectWriter) abortUpload() error {
+	if w.uploadID == "" {
+		return nil
+	}
+	uploadID := w.uploadID
+	w.uploadID = ""
+	return w.storage.abortMultipartUpload(context.WithoutCancel(w.ctx), w.key, uploadID)
+}
@@ -0,0 +1,142 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// RangeOpener 支持按范围读取对象的存储后端
+//
+// 对象存储通过Range请求只传输需要的部分，用于断点续传和分段下载
+type RangeOpener interface {
+	OpenRange(ctx context.Context, path string, offset, length int64) (io.ReadCloser, error)
+}
+
+// OpenSeekable 打开可随机定位的对象读取器，用于响应HTTP Range请求
+//
+// 读取器在第一次Read时才打开对象，Seek只记录位置：
+//   - 后端返回的读取器本身可定位(本地文件)时直接定位
+//   - 后端实现RangeOpener时从目标位置重新发起范围读取
+//   - 其他后端向前定位时丢弃中间数据，向后定位时重新打开对象
+//
+// size为对象大小，通常来自文件记录或Stat
+func OpenSeekable(ctx context.Context, store Storage, path string, size int64) io.ReadSeekCloser {
+	return &seekableObject{ctx: ctx, store: store, path: path, size: size}
+}
+
+// seekableObject 按需打开的可定位对象读取器
+type seekableObject struct {
+	ctx    context.Context
+	store  Storage
+	path   string
+	size   int64
+	offset int64 // 逻辑读取位置
+
+	reader    io.ReadCloser
+	readerPos int64 // reader的实际读取位置
+}
+
+// Read 从当前位置读取
+func (o *seekableObject) Read(p []byte) (int, error) {
+	if o.offset >= o.size {
+		return 0, io.EOF
+	}
+	if err := o.position(); err != nil {
+		return 0, err
+	}
+
+	if remaining := o.size - o.offset; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	n, err := o.reader.Read(p)
+	o.offset += int64(n)
+	o.readerPos += int64(n)
+	if errors.Is(err, io.EOF) && o.offset < o.size {
+		return n, io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+// Seek 设置下一次读取的位置
+func (o *seekableObject) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += o.offset
+	case io.SeekEnd:
+		offset += o.size
+	default:
+		return 0, fmt.Errorf("无效的定位方式: %d", whence)
+	}
+	if offset < 0 {
+		return 0, fmt.Errorf("定位位置不能为负数")
+	}
+	o.offset = offset
+	return offset, nil
+}
+
+// Close 关闭已打开的对象
+func (o *seekableObject) Close() error {
+	if o.reader == nil {
+		return nil
+	}
+	err := o.reader.Close()
+	o.reader = nil
+	return err
+}
+
+// position 保证reader位于逻辑读取位置
+func (o *seekableObject) position() error {
+	if o.reader != nil && o.readerPos == o.offset {
+		return nil
+	}
+
+	if o.reader != nil {
+		if seeker, ok := o.reader.(io.Seeker); ok {
+			if _, err := seeker.Seek(o.offset, io.SeekStart); err != nil {
+				return fmt.Errorf("定位存储对象失败: %w", err)
+			}
+			o.readerPos = o.offset
+			return nil
+		}
+		if _, ok := o.store.(RangeOpener); !ok && o.readerPos < o.offset {
+			return o.discard()
+		}
+		_ = o.Close()
+	}
+
+	if ranger, ok := o.store.(RangeOpener); ok {
+		reader, err := ranger.OpenRange(o.ctx, o.path, o.offset, o.size-o.offset)
+		if err != nil {
+			return err
+		}
+		o.reader, o.readerPos = reader, o.offset
+		return nil
+	}
+
+	reader, err := o.store.Open(o.ctx, o.path)
+	if err != nil {
+		return err
+	}
+	o.reader, o.readerPos = reader, 0
+	if seeker, ok := reader.(io.Seeker); ok && o.offset > 0 {
+		if _, err := seeker.Seek(o.offset, io.SeekStart); err != nil {
+			return fmt.Errorf("定位存储对象失败: %w", err)
+		}
+		o.readerPos = o.offset
+		return nil
+	}
+	return o.discard()
+}
+
+// discard 丢弃数据直到逻辑读取位置
+func (o *seekableObject) discard() error {
+	skipped, err := io.CopyN(io.Discard, o.reader, o.offset-o.readerPos)
+	o.readerPos += skipped
+	if err != nil {
+		return fmt.Errorf("定位存储对象失败: %w", err)
+	}
+	return nil
+}
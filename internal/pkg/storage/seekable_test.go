@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sequentialStorage 只能顺序读取的存储，用于验证丢弃数据的定位方式
+type sequentialStorage struct {
+	Storage
+	data  string
+	opens int
+}
+
+func (s *sequentialStorage) Open(ctx context.Context, path string) (io.ReadCloser, error) {
+	s.opens++
+	return io.NopCloser(strings.NewReader(s.data)), nil
+}
+
+func TestOpenSeekable(t *testing.T) {
+	ctx := context.Background()
+	content := "0123456789abcdef"
+
+	local, err := NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, local.Put(ctx, "obj", strings.NewReader(content), int64(len(content))))
+
+	fake := newFakeS3()
+	fake.objects["obj"] = []byte(content)
+	s3 := newTestS3Storage(t, fake, 1024)
+
+	stores := map[string]Storage{
+		"local":      local,
+		"range":      s3,
+		"sequential": &sequentialStorage{data: content},
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			reader := OpenSeekable(ctx, store, "obj", int64(len(content)))
+			defer reader.Close()
+
+			size, err := reader.Seek(0, io.SeekEnd)
+			require.NoError(t, err)
+			assert.Equal(t, int64(len(content)), size)
+
+			buf := make([]byte, 4)
+			_, err = reader.Seek(10, io.SeekStart)
+			require.NoError(t, err)
+			_, err = io.ReadFull(reader, buf)
+			require.NoError(t, err)
+			assert.Equal(t, "abcd", string(buf))
+
+			// 向回定位
+			_, err = reader.Seek(2, io.SeekStart)
+			require.NoError(t, err)
+			_, err = io.ReadFull(reader, buf)
+			require.NoError(t, err)
+			assert.Equal(t, "2345", string(buf))
+
+			rest, err := io.ReadAll(reader)
+			require.NoError(t, err)
+			assert.Equal(t, content[6:], string(rest))
+		})
+	}
+}
+
+func TestOpenSeekable_ServeContentRange(t *testing.T) {
+	fake := newFakeS3()
+	fake.objects["obj"] = bytes.Repeat([]byte("x"), 100)
+	fake.objects["obj"][50] = 'y'
+	store := newTestS3Storage(t, fake, 1024)
+
+	req := httptest.NewRequest(http.MethodGet, "/download", nil)
+	req.Header.Set("Range", "bytes=50-59")
+	w := httptest.NewRecorder()
+	reader := OpenSeekable(context.Background(), store, "obj", 100)
+	http.ServeContent(w, req, "", time.Time{}, reader)
+	require.NoError(t, reader.Close())
+
+	assert.Equal(t, http.StatusPartialContent, w.Code)
+	assert.Equal(t, "bytes 50-59/100", w.Header().Get("Content-Range"))
+	assert.Equal(t, "yxxxxxxxxx", w.Body.String())
+	assert.Contains(t, fake.requests, "GET obj ", "只发起一次范围读取")
+}
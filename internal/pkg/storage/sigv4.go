@@ -0,0 +1,239 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SigV4 签名相关常量
+const (
+	sigV4Algorithm   = "AWS4-HMAC-SHA256"
+	sigV4DateFormat  = "20060102"
+	sigV4TimeFormat  = "20060102T150405Z"
+	sigV4Service     = "s3"
+	sigV4Terminator  = "aws4_request"
+	unsignedPayload  = "UNSIGNED-PAYLOAD"
+	emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+// SigV4Signer AWS Signature Version 4 签名器
+//
+// S3及兼容S3协议的对象存储(MinIO、阿里云OSS的S3兼容接口)均使用该签名算法，
+// 签名器不依赖任何SDK，可用于请求头签名和浏览器POST表单策略签名
+type SigV4Signer struct {
+	accessKeyID     string
+	secretAccessKey string
+	region          string
+}
+
+// NewSigV4Signer 创建SigV4签名器，region为空时使用us-east-1
+func NewSigV4Signer(accessKeyID, secretAccessKey, region string) *SigV4Signer {
+	if region == "" {
+		region = "us-east-1"
+	}
+	return &SigV4Signer{
+		accessKeyID:     accessKeyID,
+		secretAccessKey: secretAccessKey,
+		region:          region,
+	}
+}
+
+// Region 返回签名使用的区域
+func (s *SigV4Signer) Region() string {
+	return s.region
+}
+
+// Credential 返回 AccessKeyID/日期/区域/服务/aws4_request 形式的凭证范围
+func (s *SigV4Signer) Credential(t time.Time) string {
+	return s.accessKeyID + "/" + s.scope(t)
+}
+
+// SignRequest 使用请求头方式签名，payloadHash为空时使用UNSIGNED-PAYLOAD
+//
+// 会设置 Host、X-Amz-Date、X-Amz-Content-Sha256 和 Authorization 请求头
+func (s *SigV4Signer) SignRequest(req *http.Request, payloadHash string, t time.Time) {
+	t = t.UTC()
+	if payloadHash == "" {
+		payloadHash = unsignedPayload
+	}
+	req.Header.Set("X-Amz-Date", t.Format(sigV4TimeFormat))
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders, canonicalHeaders := canonicalizeHeaders(req)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalURI(req.URL),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	stringToSign := strings.Join([]string{
+		sigV4Algorithm,
+		t.Format(sigV4TimeFormat),
+		s.scope(t),
+		hashHex([]byte(canonicalRequest)),
+	}, "\n")
+
+	req.Header.Set("Authorization", sigV4Algorithm+
+		" Credential="+s.Credential(t)+
+		", SignedHeaders="+signedHeaders+
+		", Signature="+hex.EncodeToString(hmacSHA256(s.signingKey(t), stringToSign)))
+}
+
+// PresignURL 使用查询参数方式签名，返回在expires内有效的地址
+//
+// 只对host请求头签名，持有地址的客户端无需密钥即可直接访问对象
+func (s *SigV4Signer) PresignURL(method string, u *url.URL, expires time.Duration, t time.Time) string {
+	t = t.UTC()
+	signed := *u
+	query := signed.Query()
+	query.Set("X-Amz-Algorithm", sigV4Algorithm)
+	query.Set("X-Amz-Credential", s.Credential(t))
+	query.Set("X-Amz-Date", t.Format(sigV4TimeFormat))
+	query.Set("X-Amz-Expires", strconv.FormatInt(int64(expires/time.Second), 10))
+	query.Set("X-Amz-SignedHeaders", "host")
+
+	canonicalRequest := strings.Join([]string{
+		method,
+		canonicalURI(&signed),
+		canonicalQuery(query),
+		"host:" + signed.Host + "\n",
+		"host",
+		unsignedPayload,
+	}, "\n")
+
+	stringToSign := strings.Join([]string{
+		sigV4Algorithm,
+		t.Format(sigV4TimeFormat),
+		s.scope(t),
+		hashHex([]byte(canonicalRequest)),
+	}, "\n")
+
+	query.Set("X-Amz-Signature", hex.EncodeToString(hmacSHA256(s.signingKey(t), stringToSign)))
+	// 与签名使用相同的编码，避免空格等字符的编码差异导致签名不匹配
+	signed.RawQuery = canonicalQuery(query)
+	return signed.String()
+}
+
+// SignPolicy 对base64编码的POST策略签名，返回十六进制签名
+func (s *SigV4Signer) SignPolicy(encodedPolicy string, t time.Time) string {
+	return hex.EncodeToString(hmacSHA256(s.signingKey(t.UTC()), encodedPolicy))
+}
+
+// scope 返回 日期/区域/服务/aws4_request
+func (s *SigV4Signer) scope(t time.Time) string {
+	return strings.Join([]string{t.UTC().Format(sigV4DateFormat), s.region, sigV4Service, sigV4Terminator}, "/")
+}
+
+// signingKey 按日期、区域、服务逐级派生签名密钥
+func (s *SigV4Signer) signingKey(t time.Time) []byte {
+	key := hmacSHA256([]byte("AWS4"+s.secretAccessKey), t.UTC().Format(sigV4DateFormat))
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, sigV4Service)
+	return hmacSHA256(key, sigV4Terminator)
+}
+
+// canonicalizeHeaders 返回参与签名的请求头列表和规范化请求头
+func canonicalizeHeaders(req *http.Request) (string, string) {
+	headers := map[string]string{"host": req.Host}
+	if headers["host"] == "" {
+		headers["host"] = req.URL.Host
+	}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if lower == "authorization" || lower == "user-agent" {
+			continue
+		}
+		trimmed := make([]string, len(values))
+		for i, v := range values {
+			trimmed[i] = strings.Join(strings.Fields(v), " ")
+		}
+		headers[lower] = strings.Join(trimmed, ",")
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonical strings.Builder
+	for _, name := range names {
+		canonical.WriteString(name)
+		canonical.WriteByte(':')
+		canonical.WriteString(headers[name])
+		canonical.WriteByte('\n')
+	}
+	return strings.Join(names, ";"), canonical.String()
+}
+
+// canonicalURI 对路径的每一段按RFC 3986编码
+func canonicalURI(u *url.URL) string {
+	path := u.EscapedPath()
+	if path == "" {
+		return "/"
+	}
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		unescaped, err := url.PathUnescape(segment)
+		if err != nil {
+			unescaped = segment
+		}
+		segments[i] = uriEncode(unescaped)
+	}
+	return strings.Join(segments, "/")
+}
+
+// canonicalQuery 按参数名排序并编码查询字符串
+func canonicalQuery(values url.Values) string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		vals := append([]string(nil), values[key]...)
+		sort.Strings(vals)
+		for _, v := range vals {
+			pairs = append(pairs, uriEncode(key)+"="+uriEncode(v))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// uriEncode 按SigV4要求编码：仅保留 A-Z a-z 0-9 - _ . ~
+func uriEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteString("%" + strings.ToUpper(hex.EncodeToString([]byte{c})))
+	}
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
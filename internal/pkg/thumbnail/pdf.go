@@ -0,0 +1,106 @@
+package thumbnail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// defaultPDFTimeout PDF渲染默认超时
+const defaultPDFTimeout = 30 * time.Second
+
+// ErrRendererDisabled 未配置PDF渲染命令
+var ErrRendererDisabled = errors.New("pdf renderer disabled")
+
+// PDFRenderer 调用外部命令(poppler-utils的pdftoppm)渲染PDF首页
+//
+// 使用示例：
+//
+//	renderer := &PDFRenderer{Command: "pdftoppm", Timeout: 30 * time.Second}
+//	img, err := renderer.RenderFirstPage(ctx, reader, 1024, DefaultMaxPixels)
+type PDFRenderer struct {
+	Command string        // 渲染命令，为空时禁用
+	Timeout time.Duration // 渲染超时，为0时使用30秒
+}
+
+// Enabled 检查是否配置了渲染命令
+func (r *PDFRenderer) Enabled() bool {
+	return r != nil && r.Command != ""
+}
+
+// RenderFirstPage 将PDF首页渲染为最长边不超过 maxSize 的图片
+//
+// PDF内容先写入临时目录再交给渲染命令，渲染结束后临时目录被删除
+func (r *PDFRenderer) RenderFirstPage(ctx context.Context, src io.Reader, maxSize, maxPixels int) (image.Image, error) {
+	if !r.Enabled() {
+		return nil, ErrRendererDisabled
+	}
+	timeout := r.Timeout
+	if timeout <= 0 {
+		timeout = defaultPDFTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	dir, err := os.MkdirTemp("", "cloudpan-pdf-*")
+	if err != nil {
+		return nil, fmt.Errorf("创建临时目录失败: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	input := filepath.Join(dir, "input.pdf")
+	if err := writeFile(input, src); err != nil {
+		return nil, err
+	}
+
+	output := filepath.Join(dir, "page")
+	cmd := exec.CommandContext(ctx, r.Command,
+		"-f", "1", "-l", "1", "-singlefile", "-png",
+		"-scale-to", strconv.Itoa(maxSize),
+		input, output)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("渲染PDF超时: %w", ctx.Err())
+		}
+		return nil, fmt.Errorf("渲染PDF失败: %w: %s", err, truncateOutput(out))
+	}
+
+	page, err := os.Open(output + ".png")
+	if err != nil {
+		return nil, fmt.Errorf("读取PDF渲染结果失败: %w", err)
+	}
+	defer page.Close()
+	return Decode(page, maxPixels)
+}
+
+// writeFile 将内容写入文件
+func writeFile(name string, src io.Reader) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return fmt.Errorf("创建临时文件失败: %w", err)
+	}
+	if _, err := io.Copy(f, src); err != nil {
+		f.Close()
+		return fmt.Errorf("写入临时文件失败: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("写入临时文件失败: %w", err)
+	}
+	return nil
+}
+
+// truncateOutput 截断命令输出，避免错误信息过长
+func truncateOutput(out []byte) string {
+	const limit = 200
+	if len(out) > limit {
+		return string(out[:limit]) + "..."
+	}
+	return string(out)
+}
@@ -0,0 +1,145 @@
+// Package thumbnail 生成图片缩略图和预览图，用于文件列表和预览页展示
+//
+// 支持JPEG、PNG和GIF(取第一帧)，输出统一为JPEG；透明区域以白色填充。
+// 解码前先读取图片尺寸，像素数超过上限的图片直接拒绝，避免解压炸弹占满内存
+package thumbnail
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // 注册GIF解码器
+	"image/jpeg"
+	_ "image/png" // 注册PNG解码器
+	"io"
+	"strings"
+)
+
+// DefaultMaxPixels 默认允许解码的最大像素数(约4000万，如8000x5000)
+const DefaultMaxPixels = 40_000_000
+
+// DefaultQuality 默认JPEG输出质量
+const DefaultQuality = 85
+
+// ErrUnsupportedFormat 不支持的图片格式
+var ErrUnsupportedFormat = errors.New("unsupported image format")
+
+// ErrImageTooLarge 图片像素数超过上限
+var ErrImageTooLarge = errors.New("image too large")
+
+// supportedTypes 支持生成缩略图的图片类型
+var supportedTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/jpg":  true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+// Supported 检查是否支持为该类型的图片生成缩略图
+func Supported(mimeType string) bool {
+	return supportedTypes[normalizeMime(mimeType)]
+}
+
+// Decode 解码图片，像素数超过 maxPixels 时返回 ErrImageTooLarge
+//
+// maxPixels 为0时使用 DefaultMaxPixels
+func Decode(src io.Reader, maxPixels int) (image.Image, error) {
+	if maxPixels <= 0 {
+		maxPixels = DefaultMaxPixels
+	}
+
+	// 读取尺寸时消耗的数据缓存下来，解码时重新拼接到输入前面
+	var header bytes.Buffer
+	reader := bufio.NewReader(src)
+	cfg, _, err := image.DecodeConfig(io.TeeReader(reader, &header))
+	if err != nil {
+		if errors.Is(err, image.ErrFormat) {
+			return nil, ErrUnsupportedFormat
+		}
+		return nil, fmt.Errorf("读取图片尺寸失败: %w", err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return nil, fmt.Errorf("图片尺寸无效: %dx%d", cfg.Width, cfg.Height)
+	}
+	if cfg.Width*cfg.Height > maxPixels {
+		return nil, fmt.Errorf("%w: %dx%d", ErrImageTooLarge, cfg.Width, cfg.Height)
+	}
+
+	img, _, err := image.Decode(io.MultiReader(&header, reader))
+	if err != nil {
+		return nil, fmt.Errorf("解码图片失败: %w", err)
+	}
+	return img, nil
+}
+
+// Resize 按比例缩小图片，使宽高都不超过 maxSize，并以白色填充透明区域
+//
+// 每个目标像素取其覆盖的源像素平均值(盒式滤波)，缩小倍数较大时也不会出现明显锯齿；
+// 图片本身不超过 maxSize 时保持原尺寸
+func Resize(img image.Image, maxSize int) *image.RGBA {
+	bounds := img.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	dstW, dstH := fitSize(srcW, srcH, maxSize)
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+
+	for dy := 0; dy < dstH; dy++ {
+		y0 := bounds.Min.Y + dy*srcH/dstH
+		y1 := max(bounds.Min.Y+(dy+1)*srcH/dstH, y0+1)
+		for dx := 0; dx < dstW; dx++ {
+			x0 := bounds.Min.X + dx*srcW/dstW
+			x1 := max(bounds.Min.X+(dx+1)*srcW/dstW, x0+1)
+
+			var r, g, b, n uint64
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					// RGBA返回预乘透明度的值，加上透明部分即为叠加到白色背景的结果
+					cr, cg, cb, ca := img.At(x, y).RGBA()
+					r += uint64(cr + 0xffff - ca)
+					g += uint64(cg + 0xffff - ca)
+					b += uint64(cb + 0xffff - ca)
+					n++
+				}
+			}
+			dst.SetRGBA(dx, dy, color.RGBA{
+				R: uint8(r / n >> 8),
+				G: uint8(g / n >> 8),
+				B: uint8(b / n >> 8),
+				A: 0xff,
+			})
+		}
+	}
+	return dst
+}
+
+// Encode 将图片编码为JPEG，quality 为0时使用 DefaultQuality
+func Encode(dst io.Writer, img image.Image, quality int) error {
+	if quality <= 0 || quality > 100 {
+		quality = DefaultQuality
+	}
+	if err := jpeg.Encode(dst, img, &jpeg.Options{Quality: quality}); err != nil {
+		return fmt.Errorf("编码缩略图失败: %w", err)
+	}
+	return nil
+}
+
+// fitSize 计算按比例缩放到 maxSize 以内的尺寸，宽高至少为1
+func fitSize(width, height, maxSize int) (int, int) {
+	if maxSize <= 0 || (width <= maxSize && height <= maxSize) {
+		return width, height
+	}
+	if width >= height {
+		return maxSize, max(height*maxSize/width, 1)
+	}
+	return max(width*maxSize/height, 1), maxSize
+}
+
+// normalizeMime 去掉MIME类型的参数部分并转为小写
+func normalizeMime(mimeType string) string {
+	if i := strings.IndexByte(mimeType, ';'); i >= 0 {
+		mimeType = mimeType[:i]
+	}
+	return strings.ToLower(strings.TrimSpace(mimeType))
+}
@@ -0,0 +1,112 @@
+package thumbnail
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// encodePNG 生成指定尺寸、指定颜色的PNG
+func encodePNG(t *testing.T, width, height int, c color.Color) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestSupported(t *testing.T) {
+	assert.True(t, Supported("image/jpeg"))
+	assert.True(t, Supported("IMAGE/PNG; charset=binary"))
+	assert.True(t, Supported("image/gif"))
+	assert.False(t, Supported("image/svg+xml"))
+	assert.False(t, Supported("application/pdf"))
+	assert.False(t, Supported(""))
+}
+
+func TestDecode(t *testing.T) {
+	t.Run("decodes png", func(t *testing.T) {
+		img, err := Decode(bytes.NewReader(encodePNG(t, 30, 20, color.White)), 0)
+		require.NoError(t, err)
+		assert.Equal(t, image.Rect(0, 0, 30, 20), img.Bounds())
+	})
+
+	t.Run("rejects images over pixel limit before decoding", func(t *testing.T) {
+		_, err := Decode(bytes.NewReader(encodePNG(t, 30, 20, color.White)), 599)
+		assert.ErrorIs(t, err, ErrImageTooLarge)
+	})
+
+	t.Run("rejects unknown formats", func(t *testing.T) {
+		_, err := Decode(strings.NewReader("%PDF-1.7 not an image"), 0)
+		assert.ErrorIs(t, err, ErrUnsupportedFormat)
+	})
+}
+
+func TestResize(t *testing.T) {
+	t.Run("keeps aspect ratio", func(t *testing.T) {
+		img, err := Decode(bytes.NewReader(encodePNG(t, 400, 100, color.White)), 0)
+		require.NoError(t, err)
+
+		assert.Equal(t, image.Rect(0, 0, 200, 50), Resize(img, 200).Bounds())
+	})
+
+	t.Run("tall images", func(t *testing.T) {
+		img := image.NewGray(image.Rect(0, 0, 10, 1000))
+		assert.Equal(t, image.Rect(0, 0, 1, 100), Resize(img, 100).Bounds())
+	})
+
+	t.Run("does not upscale", func(t *testing.T) {
+		img := image.NewGray(image.Rect(0, 0, 40, 30))
+		assert.Equal(t, image.Rect(0, 0, 40, 30), Resize(img, 256).Bounds())
+	})
+
+	t.Run("averages source pixels", func(t *testing.T) {
+		// 左右两列黑白交替，缩小到一半后应为灰色
+		img := image.NewGray(image.Rect(0, 0, 4, 4))
+		for y := 0; y < 4; y++ {
+			for x := 0; x < 4; x += 2 {
+				img.SetGray(x, y, color.Gray{Y: 0xff})
+			}
+		}
+		c := Resize(img, 2).RGBAAt(0, 0)
+		assert.InDelta(t, 0x7f, c.R, 1)
+		assert.Equal(t, uint8(0xff), c.A)
+	})
+
+	t.Run("flattens transparency onto white", func(t *testing.T) {
+		img, err := Decode(bytes.NewReader(encodePNG(t, 8, 8, color.NRGBA{R: 0xff, A: 0})), 0)
+		require.NoError(t, err)
+
+		assert.Equal(t, color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}, Resize(img, 4).RGBAAt(0, 0))
+	})
+}
+
+func TestEncode(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Encode(&buf, image.NewRGBA(image.Rect(0, 0, 16, 9)), 0))
+
+	img, err := jpeg.Decode(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 16, 9), img.Bounds())
+}
+
+func TestPDFRenderer_Disabled(t *testing.T) {
+	var renderer *PDFRenderer
+	assert.False(t, renderer.Enabled())
+
+	_, err := (&PDFRenderer{}).RenderFirstPage(context.Background(), strings.NewReader("%PDF"), 256, 0)
+	assert.ErrorIs(t, err, ErrRendererDisabled)
+}
@@ -0,0 +1,208 @@
+// Package tracing 分布式追踪(OpenTelemetry)
+//
+// Init 按 monitoring.tracing 配置创建全局 TracerProvider 和W3C Trace Context传播器，
+// 未启用时保持OpenTelemetry默认的空实现，Start 创建的span不记录任何数据。
+// HTTP中间件为每个请求创建根span，请求上下文经处理器传入服务、仓储(GORM插件)和Redis调用，
+// 这些调用创建的span都挂在请求span下。
+//
+// 使用示例：
+//
+//	shutdown, err := tracing.Init(ctx, config.AppConfig.Monitoring.Tracing, tracing.ServiceInfo{Name: "cloudpan", Version: "1.2.0"})
+//	defer shutdown(context.Background())
+//
+//	func (s *service) Register(ctx context.Context, req *Request) (err error) {
+//		ctx, span := tracing.Start(ctx, "UserService.Register")
+//		defer tracing.Finish(span, &err)
+//		...
+//	}
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"cloudpan/internal/pkg/config"
+)
+
+// InstrumentationName 本服务创建span使用的instrumentation名称
+const InstrumentationName = "cloudpan"
+
+// 导出器类型
+const (
+	ExporterOTLP   = "otlp"
+	ExporterJaeger = "jaeger" // Jaeger的OTLP接收端，与otlp相同
+	ExporterStdout = "stdout"
+)
+
+// OTLP传输协议
+const (
+	ProtocolHTTP = "http"
+	ProtocolGRPC = "grpc"
+)
+
+// 默认值
+const (
+	defaultHTTPEndpoint = "localhost:4318"
+	defaultGRPCEndpoint = "localhost:4317"
+	defaultTimeout      = 10 * time.Second
+)
+
+// enabled 是否已启用追踪
+var enabled atomic.Bool
+
+// ServiceInfo 上报的服务信息
+type ServiceInfo struct {
+	Name        string // 服务名，配置了 service_name 时以配置为准
+	Version     string // 服务版本
+	Environment string // 部署环境
+}
+
+// Init 按配置初始化全局追踪，返回的关闭函数在退出前调用以导出剩余的span
+//
+// 未启用追踪时不做任何设置，返回的关闭函数为空操作
+func Init(ctx context.Context, cfg config.TracingConfig, service ServiceInfo) (func(context.Context) error, error) {
+	if !cfg.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := newExporter(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	name := cfg.ServiceName
+	if name == "" {
+		name = service.Name
+	}
+	attrs := []attribute.KeyValue{semconv.ServiceName(name)}
+	if service.Version != "" {
+		attrs = append(attrs, semconv.ServiceVersion(service.Version))
+	}
+	if service.Environment != "" {
+		attrs = append(attrs, semconv.DeploymentEnvironment(service.Environment))
+	}
+	if host, err := os.Hostname(); err == nil {
+		attrs = append(attrs, semconv.ServiceInstanceID(host))
+	}
+
+	ratio := cfg.SampleRatio
+	if ratio <= 0 {
+		ratio = 1
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, attrs...)),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	enabled.Store(true)
+
+	return func(ctx context.Context) error {
+		enabled.Store(false)
+		return provider.Shutdown(ctx)
+	}, nil
+}
+
+// newExporter 按配置创建span导出器
+func newExporter(ctx context.Context, cfg config.TracingConfig) (sdktrace.SpanExporter, error) {
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	switch strings.ToLower(cfg.Exporter) {
+	case "", ExporterOTLP, ExporterJaeger:
+	case ExporterStdout:
+		return stdouttrace.New(stdouttrace.WithPrettyPrint())
+	default:
+		return nil, fmt.Errorf("unsupported tracing exporter: %s", cfg.Exporter)
+	}
+
+	switch strings.ToLower(cfg.Protocol) {
+	case "", ProtocolHTTP:
+		options := []otlptracehttp.Option{
+			otlptracehttp.WithEndpoint(endpointOrDefault(cfg.Endpoint, defaultHTTPEndpoint)),
+			otlptracehttp.WithTimeout(timeout),
+		}
+		if cfg.Insecure {
+			options = append(options, otlptracehttp.WithInsecure())
+		}
+		return otlptracehttp.New(ctx, options...)
+	case ProtocolGRPC:
+		options := []otlptracegrpc.Option{
+			otlptracegrpc.WithEndpoint(endpointOrDefault(cfg.Endpoint, defaultGRPCEndpoint)),
+			otlptracegrpc.WithTimeout(timeout),
+		}
+		if cfg.Insecure {
+			options = append(options, otlptracegrpc.WithInsecure())
+		}
+		return otlptracegrpc.New(ctx, options...)
+	default:
+		return nil, fmt.Errorf("unsupported tracing protocol: %s", cfg.Protocol)
+	}
+}
+
+// endpointOrDefault 返回配置的接收端地址，未配置时返回默认地址
+func endpointOrDefault(endpoint, fallback string) string {
+	if endpoint = strings.TrimSpace(endpoint); endpoint != "" {
+		return endpoint
+	}
+	return fallback
+}
+
+// Enabled 是否已启用追踪，GORM插件和Redis钩子只在启用时注册
+func Enabled() bool {
+	return enabled.Load()
+}
+
+// Tracer 返回本服务的Tracer，每次从全局 TracerProvider 获取，Init 之前获取的也会生效
+func Tracer() trace.Tracer {
+	return otel.Tracer(InstrumentationName)
+}
+
+// Start 在ctx的追踪链路下创建内部span
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// Finish 结束span，errp指向的错误不为nil时记录错误并将span标记为失败
+//
+// 与具名返回值配合在defer中调用：defer tracing.Finish(span, &err)
+func Finish(span trace.Span, errp *error) {
+	if errp != nil && *errp != nil {
+		span.RecordError(*errp)
+		span.SetStatus(codes.Error, (*errp).Error())
+	}
+	span.End()
+}
+
+// TraceID 返回ctx所在链路的追踪ID，不在链路中时返回空字符串
+func TraceID(ctx context.Context) string {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.HasTraceID() {
+		return ""
+	}
+	return spanContext.TraceID().String()
+}
+
+// InTrace 判断ctx是否在已采样的链路中，数据库和Redis调用只在请求链路中创建span，避免后台任务产生大量孤立的根span
+func InTrace(ctx context.Context) bool {
+	return trace.SpanContextFromContext(ctx).IsSampled()
+}
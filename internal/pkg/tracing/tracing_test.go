@@ -0,0 +1,85 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"cloudpan/internal/pkg/config"
+)
+
+// useRecorder 将全局 TracerProvider 替换为记录span的实现，测试结束后恢复
+func useRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func TestInit_Disabled(t *testing.T) {
+	shutdown, err := Init(context.Background(), config.TracingConfig{}, ServiceInfo{Name: "cloudpan"})
+	require.NoError(t, err)
+	assert.False(t, Enabled())
+	assert.NoError(t, shutdown(context.Background()))
+}
+
+func TestInit_Stdout(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	defer otel.SetTracerProvider(previous)
+
+	shutdown, err := Init(context.Background(), config.TracingConfig{Enabled: true, Exporter: ExporterStdout}, ServiceInfo{Name: "cloudpan"})
+	require.NoError(t, err)
+	assert.True(t, Enabled())
+
+	ctx, span := Start(context.Background(), "test")
+	assert.True(t, InTrace(ctx))
+	assert.Len(t, TraceID(ctx), 32)
+	span.End()
+
+	require.NoError(t, shutdown(context.Background()))
+	assert.False(t, Enabled())
+}
+
+func TestNewExporter_Unsupported(t *testing.T) {
+	_, err := newExporter(context.Background(), config.TracingConfig{Exporter: "zipkin"})
+	assert.Error(t, err)
+
+	_, err = newExporter(context.Background(), config.TracingConfig{Protocol: "thrift"})
+	assert.Error(t, err)
+}
+
+func TestStartFinish(t *testing.T) {
+	recorder := useRecorder(t)
+
+	func() (err error) {
+		ctx, span := Start(context.Background(), "UserService.CreateUser")
+		defer Finish(span, &err)
+
+		_, child := Start(ctx, "child")
+		Finish(child, nil)
+		return errors.New("邮箱已被注册")
+	}()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	child, parent := spans[0], spans[1]
+	assert.Equal(t, "child", child.Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), child.Parent().SpanID())
+	assert.Equal(t, codes.Unset, child.Status().Code)
+	assert.Equal(t, codes.Error, parent.Status().Code)
+	assert.Equal(t, "邮箱已被注册", parent.Status().Description)
+}
+
+func TestTraceID_OutsideTrace(t *testing.T) {
+	assert.Empty(t, TraceID(context.Background()))
+	assert.False(t, InTrace(context.Background()))
+}
@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// CopyBufferSize 流式复制使用的缓冲区大小
+const CopyBufferSize = 32 * 1024
+
+// maxPooledBufferSize 归还到池中的 bytes.Buffer 最大容量，
+// 超过该容量的缓冲区直接丢弃，避免个别大对象长期占用内存
+const maxPooledBufferSize = 16 * 1024 * 1024
+
+var copyBufferPool = sync.Pool{
+	New: func() interface{} {
+		buf := make([]byte, CopyBufferSize)
+		return &buf
+	},
+}
+
+var bytesBufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// GetCopyBuffer 从池中获取固定大小的复制缓冲区，使用完后需调用 PutCopyBuffer 归还
+func GetCopyBuffer() *[]byte {
+	return copyBufferPool.Get().(*[]byte)
+}
+
+// PutCopyBuffer 归还复制缓冲区
+func PutCopyBuffer(buf *[]byte) {
+	if buf == nil || len(*buf) != CopyBufferSize {
+		return
+	}
+	copyBufferPool.Put(buf)
+}
+
+// PooledCopy 使用池化缓冲区复制数据，行为与 io.Copy 一致
+//
+// io.Copy 每次调用都会分配32KB缓冲区，大量并发的下载、导出和分片校验
+// 会产生持续的GC压力；复用缓冲区可以让长时间运行时的内存保持平稳
+func PooledCopy(dst io.Writer, src io.Reader) (int64, error) {
+	buf := GetCopyBuffer()
+	defer PutCopyBuffer(buf)
+	return io.CopyBuffer(dst, src, *buf)
+}
+
+// GetBytesBuffer 从池中获取已清空的 bytes.Buffer，使用完后需调用 PutBytesBuffer 归还
+func GetBytesBuffer() *bytes.Buffer {
+	buf := bytesBufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+// PutBytesBuffer 归还 bytes.Buffer，容量过大的缓冲区不会被复用
+func PutBytesBuffer(buf *bytes.Buffer) {
+	if buf == nil || buf.Cap() > maxPooledBufferSize {
+		return
+	}
+	buf.Reset()
+	bytesBufferPool.Put(buf)
+}
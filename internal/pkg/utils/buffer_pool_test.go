@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPooledCopy(t *testing.T) {
+	data := strings.Repeat("cloudpan", CopyBufferSize/4)
+
+	var dst bytes.Buffer
+	n, err := PooledCopy(&dst, strings.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(data)), n)
+	assert.Equal(t, data, dst.String())
+}
+
+func TestCopyBufferPool(t *testing.T) {
+	buf := GetCopyBuffer()
+	assert.Len(t, *buf, CopyBufferSize)
+	PutCopyBuffer(buf)
+
+	// 大小不符的缓冲区不会进入池中
+	small := make([]byte, 10)
+	PutCopyBuffer(&small)
+	PutCopyBuffer(nil)
+	assert.Len(t, *GetCopyBuffer(), CopyBufferSize)
+}
+
+func TestBytesBufferPool(t *testing.T) {
+	buf := GetBytesBuffer()
+	buf.WriteString("leftover")
+	PutBytesBuffer(buf)
+
+	assert.Zero(t, GetBytesBuffer().Len())
+
+	large := bytes.NewBuffer(make([]byte, 0, maxPooledBufferSize+1))
+	PutBytesBuffer(large)
+	PutBytesBuffer(nil)
+}
+
+func BenchmarkPooledCopy(b *testing.B) {
+	data := bytes.Repeat([]byte("x"), 256*1024)
+	// 隐藏 WriterTo/ReaderFrom，确保走缓冲区复制路径
+	dst := struct{ io.Writer }{io.Discard}
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := PooledCopy(dst, io.LimitReader(bytes.NewReader(data), int64(len(data)))); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
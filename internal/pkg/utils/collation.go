@@ -0,0 +1,109 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+// 名称排序规则
+const (
+	CollationBinary  = "binary"  // 按字节排序，由数据库索引支持
+	CollationNatural = "natural" // 自然排序，名称中的数字按数值比较，file2 排在 file10 之前
+	CollationLocale  = "locale"  // 按语言习惯排序(中文按拼音)，名称中的数字同样按数值比较
+)
+
+// IsValidCollation 判断是否为支持的名称排序规则
+func IsValidCollation(collation string) bool {
+	switch collation {
+	case CollationBinary, CollationNatural, CollationLocale:
+		return true
+	}
+	return false
+}
+
+// NewNameComparator 按排序规则创建名称比较函数
+//
+// locale 为BCP 47语言标签(如 zh-CN、en-US)，只用于 CollationLocale，无效时使用Unicode通用排序。
+// 比较结果相同的名称再按字节比较，保证排序稳定。
+// 返回的函数内部复用排序缓冲区，不能在多个goroutine间共享
+func NewNameComparator(collation, locale string) func(a, b string) int {
+	switch collation {
+	case CollationNatural:
+		return CompareNatural
+	case CollationLocale:
+		tag, err := language.Parse(locale)
+		if err != nil {
+			tag = language.Und
+		}
+		collator := collate.New(tag, collate.Numeric, collate.IgnoreCase)
+		return func(a, b string) int {
+			if result := collator.CompareString(a, b); result != 0 {
+				return result
+			}
+			return strings.Compare(a, b)
+		}
+	default:
+		return strings.Compare
+	}
+}
+
+// CompareNatural 自然排序比较两个名称
+//
+// 连续的ASCII数字按数值比较(忽略前导零)，其他字符忽略大小写逐字符比较；
+// 比较结果相同(如 a01 与 a1、File 与 file)时按字节比较
+func CompareNatural(a, b string) int {
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if isASCIIDigit(a[i]) && isASCIIDigit(b[j]) {
+			startA, startB := i, j
+			for i < len(a) && isASCIIDigit(a[i]) {
+				i++
+			}
+			for j < len(b) && isASCIIDigit(b[j]) {
+				j++
+			}
+			numA := strings.TrimLeft(a[startA:i], "0")
+			numB := strings.TrimLeft(b[startB:j], "0")
+			if len(numA) != len(numB) {
+				return compareInt(len(numA), len(numB))
+			}
+			if result := strings.Compare(numA, numB); result != 0 {
+				return result
+			}
+			continue
+		}
+
+		ra, sizeA := utf8.DecodeRuneInString(a[i:])
+		rb, sizeB := utf8.DecodeRuneInString(b[j:])
+		if la, lb := unicode.ToLower(ra), unicode.ToLower(rb); la != lb {
+			return compareInt(int(la), int(lb))
+		}
+		i += sizeA
+		j += sizeB
+	}
+
+	if result := compareInt(len(a)-i, len(b)-j); result != 0 {
+		return result
+	}
+	return strings.Compare(a, b)
+}
+
+// isASCIIDigit 判断是否为ASCII数字
+func isASCIIDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// compareInt 比较两个整数，返回-1、0或1
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareNatural(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"file2", "file10", -1},
+		{"file10", "file2", 1},
+		{"File2", "file10", -1}, // 忽略大小写
+		{"a01", "a1", -1},       // 数值相同时按字节比较
+		{"a007b", "a7c", -1},
+		{"img", "img1", -1},
+		{"v1.10", "v1.9", 1},
+		{"same", "same", 0},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, CompareNatural(tt.a, tt.b), "%s vs %s", tt.a, tt.b)
+	}
+}
+
+func TestNewNameComparator(t *testing.T) {
+	sorted := func(collation, locale string, names ...string) []string {
+		compare := NewNameComparator(collation, locale)
+		sort.Slice(names, func(i, j int) bool { return compare(names[i], names[j]) < 0 })
+		return names
+	}
+
+	assert.Equal(t, []string{"B", "a", "file10", "file2"}, sorted(CollationBinary, "", "file2", "a", "file10", "B"))
+	assert.Equal(t, []string{"a", "B", "file2", "file10"}, sorted(CollationNatural, "", "file2", "a", "file10", "B"))
+	// 中文按拼音排序：李(li) 在 王(wang) 之前，王 在 张(zhang) 之前
+	assert.Equal(t, []string{"file2", "file10", "李四", "王五", "张三"}, sorted(CollationLocale, "zh-CN", "张三", "file10", "王五", "李四", "file2"))
+	// 无效语言使用通用规则，数字同样按数值比较
+	assert.Equal(t, []string{"a", "B", "file2", "file10"}, sorted(CollationLocale, "not a locale!", "file10", "B", "file2", "a"))
+
+	assert.True(t, IsValidCollation(CollationNatural))
+	assert.False(t, IsValidCollation("pinyin"))
+}
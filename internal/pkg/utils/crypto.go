@@ -54,15 +54,26 @@ type JWTClaims struct {
 	Email     string `json:"email"`
 	Role      string `json:"role"`
 	TokenType string `json:"token_type"` // "access" 或 "refresh"
+
+	// 模拟登录令牌的附加声明，普通令牌为空
+	ImpersonatorID uint64 `json:"impersonator_id,omitempty"` // 发起模拟登录的管理员ID
+	ReadOnly       bool   `json:"read_only,omitempty"`       // 只读模式，只允许查询请求
 	jwt.RegisteredClaims
 }
 
+// IsImpersonation 判断是否为管理员模拟登录签发的令牌
+func (c *JWTClaims) IsImpersonation() bool {
+	return c.ImpersonatorID != 0
+}
+
 // JWTManager JWT管理器接口
 type JWTManager interface {
 	GenerateAccessToken(userID uint64, username, email, role string) (string, error)
 	GenerateRefreshToken(userID uint64, username, email, role string) (string, error)
 	ValidateToken(tokenString string) (*JWTClaims, error)
 	RefreshToken(refreshToken string) (string, string, error)
+	// GenerateImpersonationToken 生成管理员模拟用户的访问令牌，有效期由调用方指定且不签发刷新令牌
+	GenerateImpersonationToken(userID uint64, username, email, role string, impersonatorID uint64, readOnly bool, expiry time.Duration) (string, error)
 }
 
 // AESCrypto AES加密接口
@@ -332,6 +343,12 @@ func VerifyPassword(hashedPassword, plainPassword string) bool {
 	return hasher.VerifyPassword(hashedPassword, plainPassword)
 }
 
+// IsPasswordHash 判断字符串是否为BCrypt哈希，用于区分历史遗留的明文密码
+func IsPasswordHash(value string) bool {
+	_, err := bcrypt.Cost([]byte(value))
+	return err == nil
+}
+
 // ValidatePasswordStrength 验证密码强度（使用默认哈希器）
 func ValidatePasswordStrength(password string) (int, error) {
 	hasher := NewDefaultPasswordHasher()
@@ -486,8 +503,38 @@ func (j *jwtManager) GenerateRefreshToken(userID uint64, username, email, role s
 	return j.generateToken(userID, username, email, role, "refresh", j.refreshExpiry)
 }
 
+// GenerateImpersonationToken 生成模拟登录访问令牌
+func (j *jwtManager) GenerateImpersonationToken(userID uint64, username, email, role string, impersonatorID uint64, readOnly bool, expiry time.Duration) (string, error) {
+	if impersonatorID == 0 {
+		return "", fmt.Errorf("模拟登录必须指定管理员ID")
+	}
+	if expiry <= 0 {
+		return "", fmt.Errorf("模拟登录令牌有效期必须大于0")
+	}
+	return j.signToken(&JWTClaims{
+		UserID:         userID,
+		Username:       username,
+		Email:          email,
+		Role:           role,
+		TokenType:      "access",
+		ImpersonatorID: impersonatorID,
+		ReadOnly:       readOnly,
+	}, expiry)
+}
+
 // generateToken 生成令牌（内部方法）
 func (j *jwtManager) generateToken(userID uint64, username, email, role, tokenType string, expiry time.Duration) (string, error) {
+	return j.signToken(&JWTClaims{
+		UserID:    userID,
+		Username:  username,
+		Email:     email,
+		Role:      role,
+		TokenType: tokenType,
+	}, expiry)
+}
+
+// signToken 补全标准声明并签名
+func (j *jwtManager) signToken(claims *JWTClaims, expiry time.Duration) (string, error) {
 	now := time.Now()
 
 	// 生成唯一的JTI
@@ -496,20 +543,13 @@ func (j *jwtManager) generateToken(userID uint64, username, email, role, tokenTy
 		return "", fmt.Errorf("生成JTI失败: %w", err)
 	}
 
-	claims := &JWTClaims{
-		UserID:    userID,
-		Username:  username,
-		Email:     email,
-		Role:      role,
-		TokenType: tokenType,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ID:        jti, // 添加唯一标识符
-			IssuedAt:  jwt.NewNumericDate(now),
-			ExpiresAt: jwt.NewNumericDate(now.Add(expiry)),
-			NotBefore: jwt.NewNumericDate(now),
-			Issuer:    "cloudpan",
-			Subject:   fmt.Sprintf("%d", userID),
-		},
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ID:        jti, // 添加唯一标识符
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(expiry)),
+		NotBefore: jwt.NewNumericDate(now),
+		Issuer:    "cloudpan",
+		Subject:   fmt.Sprintf("%d", claims.UserID),
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
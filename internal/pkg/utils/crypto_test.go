@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewPasswordHasher(t *testing.T) {
@@ -413,6 +414,43 @@ func TestJWTTokenRefresh(t *testing.T) {
 	})
 }
 
+func TestJWTImpersonationToken(t *testing.T) {
+	secretKey := "this-is-a-very-long-secret-key-for-testing-jwt-manager"
+	manager, _ := NewDefaultJWTManager(secretKey)
+
+	t.Run("模拟登录令牌携带管理员ID", func(t *testing.T) {
+		token, err := manager.GenerateImpersonationToken(12345, "testuser", "test@example.com", "user", 7, true, 15*time.Minute)
+		require.NoError(t, err)
+
+		claims, err := manager.ValidateToken(token)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(12345), claims.UserID)
+		assert.Equal(t, "access", claims.TokenType)
+		assert.True(t, claims.IsImpersonation())
+		assert.Equal(t, uint64(7), claims.ImpersonatorID)
+		assert.True(t, claims.ReadOnly)
+		assert.WithinDuration(t, time.Now().Add(15*time.Minute), claims.ExpiresAt.Time, 2*time.Second)
+
+		// 模拟登录令牌不能换取新令牌
+		_, _, err = manager.RefreshToken(token)
+		assert.Error(t, err)
+	})
+
+	t.Run("普通令牌不是模拟登录", func(t *testing.T) {
+		token, _ := manager.GenerateAccessToken(12345, "testuser", "test@example.com", "user")
+		claims, err := manager.ValidateToken(token)
+		require.NoError(t, err)
+		assert.False(t, claims.IsImpersonation())
+	})
+
+	t.Run("缺少管理员ID或有效期", func(t *testing.T) {
+		_, err := manager.GenerateImpersonationToken(12345, "testuser", "test@example.com", "user", 0, false, time.Minute)
+		assert.Error(t, err)
+		_, err = manager.GenerateImpersonationToken(12345, "testuser", "test@example.com", "user", 7, false, 0)
+		assert.Error(t, err)
+	})
+}
+
 // ==== 随机字符串生成测试 ====
 
 func TestGenerateVerificationCode(t *testing.T) {
@@ -0,0 +1,141 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FieldsQueryParam 稀疏字段集查询参数
+const FieldsQueryParam = "fields"
+
+// 稀疏字段集的解析限制，避免构造过大的字段树
+const (
+	maxFieldPaths = 100 // 最多解析的字段路径数
+	maxFieldDepth = 5   // 字段路径的最大层级
+)
+
+// FieldSet 稀疏字段集
+//
+// 键为JSON字段名，值为嵌套字段的子集，nil表示保留整个字段。
+// 由 ?fields=name,size,owner.email 形式的查询参数解析得到：
+// 逗号分隔多个字段，点号选择嵌套对象的字段，数组按元素逐个筛选。
+// 对象中的id字段总是保留，便于客户端关联数据
+type FieldSet map[string]FieldSet
+
+// ParseFieldSet 解析逗号分隔的字段列表，没有有效字段时返回nil
+//
+// 包含空层级的路径(如 "owner." 或 "a..b")被忽略
+func ParseFieldSet(raw string) FieldSet {
+	fields := FieldSet{}
+	paths := strings.Split(raw, ",")
+	if len(paths) > maxFieldPaths {
+		paths = paths[:maxFieldPaths]
+	}
+	for _, path := range paths {
+		parts := strings.Split(strings.TrimSpace(path), ".")
+		if len(parts) > maxFieldDepth || containsEmpty(parts) {
+			continue
+		}
+		fields.add(parts)
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return fields
+}
+
+// add 将字段路径加入字段集
+func (f FieldSet) add(parts []string) {
+	name := parts[0]
+	child, exists := f[name]
+	if len(parts) == 1 {
+		f[name] = nil
+		return
+	}
+	if exists && child == nil {
+		// 已请求整个字段
+		return
+	}
+	if !exists {
+		child = FieldSet{}
+		f[name] = child
+	}
+	child.add(parts[1:])
+}
+
+// Filter 按字段集筛选数据
+//
+// 数据先按JSON标签序列化，筛选后的结果是JSON兼容的map和slice，数字保持原始精度。
+// 字段集为空、数据为nil或序列化失败时原样返回
+func (f FieldSet) Filter(data interface{}) interface{} {
+	if len(f) == 0 || data == nil {
+		return data
+	}
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return data
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	var generic interface{}
+	if err := decoder.Decode(&generic); err != nil {
+		return data
+	}
+	return f.apply(generic)
+}
+
+// apply 对JSON值应用字段集
+func (f FieldSet) apply(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		filtered := make(map[string]interface{}, len(f)+1)
+		for name, child := range f {
+			fieldValue, ok := v[name]
+			if !ok {
+				continue
+			}
+			if child == nil {
+				filtered[name] = fieldValue
+			} else {
+				filtered[name] = child.apply(fieldValue)
+			}
+		}
+		if id, ok := v["id"]; ok {
+			filtered["id"] = id
+		}
+		return filtered
+	case []interface{}:
+		for i := range v {
+			v[i] = f.apply(v[i])
+		}
+		return v
+	default:
+		return value
+	}
+}
+
+// selectFields 按请求的fields参数筛选响应数据，未指定时原样返回
+func selectFields(c *gin.Context, data interface{}) interface{} {
+	if c.Request == nil {
+		return data
+	}
+	raw := c.Query(FieldsQueryParam)
+	if raw == "" {
+		return data
+	}
+	return ParseFieldSet(raw).Filter(data)
+}
+
+// containsEmpty 检查是否包含空字符串
+func containsEmpty(parts []string) bool {
+	for _, part := range parts {
+		if part == "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fieldsTestOwner struct {
+	ID    uint   `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+type fieldsTestFile struct {
+	ID    uint             `json:"id"`
+	Name  string           `json:"name"`
+	Size  int64            `json:"size"`
+	Path  string           `json:"path"`
+	Owner *fieldsTestOwner `json:"owner"`
+}
+
+func TestParseFieldSet(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		expected FieldSet
+	}{
+		{"empty", "", nil},
+		{"only separators", " , ,", nil},
+		{"flat", "name, size", FieldSet{"name": nil, "size": nil}},
+		{"nested", "owner.email,owner.name", FieldSet{"owner": {"email": nil, "name": nil}}},
+		{"whole field wins", "owner.email,owner", FieldSet{"owner": nil}},
+		{"whole field first", "owner,owner.email", FieldSet{"owner": nil}},
+		{"empty segment ignored", "owner.,a..b,name", FieldSet{"name": nil}},
+		{"too deep ignored", "a.b.c.d.e.f,name", FieldSet{"name": nil}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ParseFieldSet(tt.raw))
+		})
+	}
+}
+
+func TestFieldSet_Filter(t *testing.T) {
+	files := []fieldsTestFile{
+		{ID: 1, Name: "a.txt", Size: 9007199254740993, Path: "/a.txt", Owner: &fieldsTestOwner{ID: 7, Name: "张三", Email: "a@example.com"}},
+		{ID: 2, Name: "b.txt", Size: 2, Path: "/b.txt"},
+	}
+
+	filtered := ParseFieldSet("name,size,owner.email").Filter(files)
+	encoded, err := json.Marshal(filtered)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"id":1,"name":"a.txt","size":9007199254740993,"owner":{"id":7,"email":"a@example.com"}},
+		{"id":2,"name":"b.txt","size":2,"owner":null}
+	]`, string(encoded))
+
+	// 未指定字段时原样返回
+	assert.Equal(t, files, FieldSet(nil).Filter(files))
+	// 标量数据不受影响
+	assert.Equal(t, json.Number("3"), ParseFieldSet("name").Filter(3))
+}
+
+func TestSuccess_SparseFields(t *testing.T) {
+	router, recorder := setupTestGin()
+	router.GET("/file", func(c *gin.Context) {
+		Success(c, fieldsTestFile{ID: 1, Name: "a.txt", Size: 1, Path: "/a.txt"})
+	})
+	router.GET("/files", func(c *gin.Context) {
+		SuccessList(c, []fieldsTestFile{{ID: 1, Name: "a.txt"}}, NewPagination(1, 20, 1))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/file?fields=name", nil)
+	router.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.Equal(t, map[string]interface{}{"id": float64(1), "name": "a.txt"}, response["data"])
+	assert.Contains(t, response, "request_id", "响应外层结构不受影响")
+
+	recorder = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/files?fields=path", nil)
+	router.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	response = nil
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.Equal(t, []interface{}{map[string]interface{}{"id": float64(1), "path": ""}}, response["data"])
+	assert.NotNil(t, response["pagination"])
+}
@@ -0,0 +1,122 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+)
+
+// MaxFileNameBytes 文件名最大字节数(UTF-8编码)，与主流文件系统的单级名称上限一致
+const MaxFileNameBytes = 255
+
+// 文件名校验失败原因
+const (
+	FileNameReasonEmpty         = "empty"          // 名称为空
+	FileNameReasonTooLong       = "too_long"       // 名称过长
+	FileNameReasonDotSegment    = "dot_segment"    // 名称为 . 或 ..
+	FileNameReasonPathSeparator = "path_separator" // 包含路径分隔符
+	FileNameReasonInvalidChar   = "invalid_char"   // 包含Windows不允许的字符
+	FileNameReasonControlChar   = "control_char"   // 包含控制字符或不可见的双向控制字符
+	FileNameReasonTrailingDot   = "trailing_dot"   // 以点结尾
+	FileNameReasonReservedName  = "reserved_name"  // Windows保留设备名
+)
+
+// fileNameInvalidChars Windows文件名中不允许出现的字符(路径分隔符单独判断)
+const fileNameInvalidChars = `<>:"|?*`
+
+// windowsReservedNames Windows保留设备名，不区分大小写，带扩展名同样保留(如 CON.txt)
+var windowsReservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// FileNameError 文件名校验错误
+//
+// Reason 为稳定的机器可读原因，客户端据此提示用户；Error() 返回中文说明
+type FileNameError struct {
+	Name   string // 规范化后的名称
+	Reason string // 失败原因，取值见 FileNameReason* 常量
+	Detail string // 中文说明
+}
+
+// Error 实现error接口
+func (e *FileNameError) Error() string {
+	return e.Detail
+}
+
+// Unwrap 文件名错误属于格式错误，可被 IsValidationError 识别为校验错误
+func (e *FileNameError) Unwrap() error {
+	return pkgErrors.ErrInvalidFormat
+}
+
+// newFileNameError 创建文件名校验错误
+func newFileNameError(name, reason, detail string) *FileNameError {
+	return &FileNameError{Name: name, Reason: reason, Detail: detail}
+}
+
+// NormalizeFileName 规范化并校验文件或文件夹名称
+//
+// 规范化：转换为NFC形式，去除首尾空白。
+// 校验：拒绝空名称、. 和 ..、路径分隔符、控制字符和双向控制字符、Windows非法字符、
+// 以点结尾的名称以及 CON、NUL 等Windows保留设备名，超过 MaxFileNameBytes 字节的名称同样拒绝。
+// 所有创建、重命名、移动和上传路径都应使用返回的名称入库
+func NormalizeFileName(name string) (string, error) {
+	if !utf8.ValidString(name) {
+		return "", newFileNameError(name, FileNameReasonInvalidChar, "文件名不是有效的UTF-8编码")
+	}
+
+	name = strings.TrimFunc(norm.NFC.String(name), unicode.IsSpace)
+	if name == "" {
+		return "", newFileNameError(name, FileNameReasonEmpty, "文件名不能为空")
+	}
+	if name == "." || name == ".." {
+		return "", newFileNameError(name, FileNameReasonDotSegment, "文件名不能为 . 或 ..")
+	}
+	if len(name) > MaxFileNameBytes {
+		return "", newFileNameError(name, FileNameReasonTooLong, fmt.Sprintf("文件名不能超过 %d 字节", MaxFileNameBytes))
+	}
+
+	for _, r := range name {
+		switch {
+		case r == '/' || r == '\\':
+			return "", newFileNameError(name, FileNameReasonPathSeparator, "文件名不能包含路径分隔符 / 或 \\")
+		case unicode.IsControl(r) || isInvisibleControl(r):
+			return "", newFileNameError(name, FileNameReasonControlChar, "文件名不能包含控制字符或不可见字符")
+		case strings.ContainsRune(fileNameInvalidChars, r):
+			return "", newFileNameError(name, FileNameReasonInvalidChar, fmt.Sprintf("文件名不能包含字符 %s", fileNameInvalidChars))
+		}
+	}
+
+	if strings.HasSuffix(name, ".") {
+		return "", newFileNameError(name, FileNameReasonTrailingDot, "文件名不能以点结尾")
+	}
+	if isWindowsReservedName(name) {
+		return "", newFileNameError(name, FileNameReasonReservedName, "文件名不能使用系统保留名称(如 CON、NUL、COM1)")
+	}
+
+	return name, nil
+}
+
+// ValidateFileName 校验文件或文件夹名称，不返回规范化结果
+func ValidateFileName(name string) error {
+	_, err := NormalizeFileName(name)
+	return err
+}
+
+// isWindowsReservedName 判断名称(去除扩展名和尾部空格后)是否为Windows保留设备名
+func isWindowsReservedName(name string) bool {
+	base := name
+	if idx := strings.IndexByte(base, '.'); idx >= 0 {
+		base = base[:idx]
+	}
+	base = strings.TrimRight(base, " ")
+	return windowsReservedNames[strings.ToUpper(base)]
+}
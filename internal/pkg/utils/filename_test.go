@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+)
+
+func TestNormalizeFileName(t *testing.T) {
+	t.Run("valid names", func(t *testing.T) {
+		tests := []struct {
+			input    string
+			expected string
+		}{
+			{"report.pdf", "report.pdf"},
+			{"  项目 计划.docx \t", "项目 计划.docx"},
+			{".gitignore", ".gitignore"},
+			{"a..b", "a..b"},
+			{"CONSOLE.txt", "CONSOLE.txt"},
+			{"cafe\u0301", "caf\u00e9"}, // 组合字符规范化为NFC
+			{strings.Repeat("a", MaxFileNameBytes), strings.Repeat("a", MaxFileNameBytes)},
+		}
+		for _, tt := range tests {
+			name, err := NormalizeFileName(tt.input)
+			require.NoError(t, err, tt.input)
+			assert.Equal(t, tt.expected, name)
+		}
+	})
+
+	t.Run("rejected names", func(t *testing.T) {
+		tests := []struct {
+			input  string
+			reason string
+		}{
+			{"", FileNameReasonEmpty},
+			{"   ", FileNameReasonEmpty},
+			{".", FileNameReasonDotSegment},
+			{" .. ", FileNameReasonDotSegment},
+			{"../etc/passwd", FileNameReasonPathSeparator},
+			{"a\\b", FileNameReasonPathSeparator},
+			{"a\x00b", FileNameReasonControlChar},
+			{"line\nbreak", FileNameReasonControlChar},
+			{"invoice\u202efdp.exe", FileNameReasonControlChar},
+			{"what?.txt", FileNameReasonInvalidChar},
+			{"a:b", FileNameReasonInvalidChar},
+			{"\xff", FileNameReasonInvalidChar},
+			{"name.", FileNameReasonTrailingDot},
+			{"name. ", FileNameReasonTrailingDot},
+			{"CON", FileNameReasonReservedName},
+			{"nul.txt", FileNameReasonReservedName},
+			{"Com1 .tar.gz", FileNameReasonReservedName},
+			{"lpt9", FileNameReasonReservedName},
+			{strings.Repeat("文", 86), FileNameReasonTooLong},
+		}
+		for _, tt := range tests {
+			_, err := NormalizeFileName(tt.input)
+			var nameErr *FileNameError
+			require.True(t, errors.As(err, &nameErr), "%q should be rejected", tt.input)
+			assert.Equal(t, tt.reason, nameErr.Reason, tt.input)
+			assert.NotEmpty(t, nameErr.Error())
+		}
+	})
+}
+
+func TestValidateFileName(t *testing.T) {
+	assert.NoError(t, ValidateFileName("photo.jpg"))
+	err := ValidateFileName("AUX")
+	assert.Error(t, err)
+	assert.True(t, pkgErrors.IsValidationError(err))
+}
@@ -55,6 +55,16 @@ const (
 	CodeDatabaseError      ResponseCode = 1021 // 数据库错误
 	CodeCacheError         ResponseCode = 1022 // 缓存错误
 	CodeConfigError        ResponseCode = 1023 // 配置错误
+	CodeInvalidFileName    ResponseCode = 1024 // 文件名不合法
+	CodeShareTransferLimit ResponseCode = 1025 // 分享流量已用尽
+	CodePendingDeletion    ResponseCode = 1026 // 账户已计划删除，可重新激活
+	CodeTwoFactorRequired  ResponseCode = 1027 // 需要两步验证，使用挑战令牌提交验证码
+	CodePreviewPending     ResponseCode = 1028 // 预览正在生成，稍后重试
+	CodeSSORequired        ResponseCode = 1029 // 邮箱域名已开启强制单点登录，不能使用密码登录
+	CodeReadOnly           ResponseCode = 1030 // 服务处于只读模式，暂不能修改数据
+	CodeFolderTooDeep      ResponseCode = 1031 // 文件夹嵌套层级超过上限
+	CodeFolderFull         ResponseCode = 1032 // 文件夹的子项数已达上限
+	CodeDownloadTooLarge   ResponseCode = 1033 // 打包下载的文件数或总大小超过上限
 )
 
 // ResponseCodeMessages 响应码对应的消息
@@ -94,6 +104,16 @@ var ResponseCodeMessages = map[ResponseCode]string{
 	CodeDatabaseError:      "数据库错误",
 	CodeCacheError:         "缓存错误",
 	CodeConfigError:        "配置错误",
+	CodeInvalidFileName:    "文件名不合法",
+	CodeShareTransferLimit: "分享流量已用尽",
+	CodePendingDeletion:    "账户待删除",
+	CodeTwoFactorRequired:  "需要两步验证",
+	CodePreviewPending:     "预览生成中",
+	CodeSSORequired:        "需要单点登录",
+	CodeReadOnly:           "服务处于只读模式",
+	CodeFolderTooDeep:      "文件夹层级过深",
+	CodeFolderFull:         "文件夹子项数已达上限",
+	CodeDownloadTooLarge:   "打包下载内容过大",
 }
 
 // Response 标准响应结构
@@ -133,6 +153,9 @@ type PageRequest struct {
 	PageSize int    `form:"page_size" json:"page_size" binding:"min=1"` // 每页大小，默认20
 	SortBy   string `form:"sort_by" json:"sort_by"`                     // 排序字段
 	SortDir  string `form:"sort_dir" json:"sort_dir"`                   // 排序方向 asc/desc
+
+	Collation string `form:"collation" json:"collation"` // 名称排序规则 binary/natural/locale，默认binary
+	Locale    string `form:"locale" json:"locale"`       // locale排序规则使用的语言，默认为请求语言
 }
 
 // GetMessage 获取响应码对应的消息
@@ -162,16 +185,20 @@ func (code ResponseCode) GetHTTPStatus() int {
 // getBusinessErrorHTTPStatus 获取业务错误码对应的HTTP状态码
 func getBusinessErrorHTTPStatus(code ResponseCode) int {
 	switch code {
-	case CodeValidationError:
+	case CodePreviewPending:
+		return http.StatusAccepted
+	case CodeValidationError, CodeInvalidFileName, CodeCaptchaRequired, CodeCaptchaWrong, CodePhoneNotVerified:
 		return http.StatusBadRequest
-	case CodeDuplicateData:
+	case CodeDuplicateData, CodeFolderTooDeep, CodeFolderFull:
 		return http.StatusConflict
 	case CodeDataNotFound:
 		return http.StatusNotFound
-	case CodeInvalidToken, CodeTokenExpired:
+	case CodeInvalidToken, CodeTokenExpired, CodeTwoFactorRequired:
 		return http.StatusUnauthorized
-	case CodePermissionDenied, CodeQuotaExceeded:
+	case CodePermissionDenied, CodeQuotaExceeded, CodeShareTransferLimit, CodePendingDeletion, CodeSSORequired, CodeDownloadTooLarge:
 		return http.StatusForbidden
+	case CodeReadOnly:
+		return http.StatusServiceUnavailable
 	default:
 		return http.StatusInternalServerError
 	}
@@ -192,7 +219,7 @@ func Success(c *gin.Context, data interface{}) {
 	response := Response{
 		Code:      CodeSuccess,
 		Message:   CodeSuccess.GetMessage(),
-		Data:      data,
+		Data:      selectFields(c, data),
 		RequestID: getRequestID(c),
 		Timestamp: time.Now().Unix(),
 	}
@@ -204,7 +231,7 @@ func SuccessWithMessage(c *gin.Context, message string, data interface{}) {
 	response := Response{
 		Code:      CodeSuccess,
 		Message:   message,
-		Data:      data,
+		Data:      selectFields(c, data),
 		RequestID: getRequestID(c),
 		Timestamp: time.Now().Unix(),
 	}
@@ -285,7 +312,7 @@ func SuccessList(c *gin.Context, data interface{}, pagination *Pagination) {
 	response := ListResponse{
 		Code:       CodeSuccess,
 		Message:    CodeSuccess.GetMessage(),
-		Data:       data,
+		Data:       selectFields(c, data),
 		Pagination: pagination,
 		RequestID:  getRequestID(c),
 		Timestamp:  time.Now().Unix(),
@@ -298,7 +325,7 @@ func SuccessListWithMessage(c *gin.Context, message string, data interface{}, pa
 	response := ListResponse{
 		Code:       CodeSuccess,
 		Message:    message,
-		Data:       data,
+		Data:       selectFields(c, data),
 		Pagination: pagination,
 		RequestID:  getRequestID(c),
 		Timestamp:  time.Now().Unix(),
@@ -342,10 +369,11 @@ func NewPagination(currentPage, pageSize int, totalCount int64) *Pagination {
 // DefaultPageRequest 获取默认分页请求参数
 func DefaultPageRequest() PageRequest {
 	return PageRequest{
-		Page:     1,
-		PageSize: 20,
-		SortBy:   "id",
-		SortDir:  "desc",
+		Page:      1,
+		PageSize:  20,
+		SortBy:    "id",
+		SortDir:   "desc",
+		Collation: CollationBinary,
 	}
 }
 
@@ -354,6 +382,9 @@ func ParsePageRequest(c *gin.Context) PageRequest {
 	var req PageRequest
 	if err := c.ShouldBindQuery(&req); err != nil {
 		req = DefaultPageRequest()
+		// 分页参数无效时仍保留排序参数
+		req.SortBy, req.SortDir = c.Query("sort_by"), c.Query("sort_dir")
+		req.Collation, req.Locale = c.Query("collation"), c.Query("locale")
 	}
 
 	// 设置默认值
@@ -372,6 +403,12 @@ func ParsePageRequest(c *gin.Context) PageRequest {
 	if req.SortDir != "asc" && req.SortDir != "desc" {
 		req.SortDir = "desc"
 	}
+	if !IsValidCollation(req.Collation) {
+		req.Collation = CollationBinary
+	}
+	if req.Locale == "" {
+		req.Locale = c.GetString("language") // 国际化中间件设置的请求语言
+	}
 
 	return req
 }
@@ -455,7 +492,7 @@ func Created(c *gin.Context, data interface{}) {
 	response := Response{
 		Code:      CodeSuccess,
 		Message:   "创建成功",
-		Data:      data,
+		Data:      selectFields(c, data),
 		RequestID: getRequestID(c),
 		Timestamp: time.Now().Unix(),
 	}
@@ -47,12 +47,22 @@ func TestResponseCode_GetHTTPStatus(t *testing.T) {
 		{CodeNotFound, http.StatusNotFound},
 		{CodeInternalError, http.StatusInternalServerError},
 		{CodeValidationError, http.StatusBadRequest},
+		{CodeInvalidFileName, http.StatusBadRequest},
+		{CodeCaptchaRequired, http.StatusBadRequest},
+		{CodeCaptchaWrong, http.StatusBadRequest},
+		{CodePhoneNotVerified, http.StatusBadRequest},
+		{CodeShareTransferLimit, http.StatusForbidden},
 		{CodeDuplicateData, http.StatusConflict},
 		{CodeDataNotFound, http.StatusNotFound},
 		{CodeInvalidToken, http.StatusUnauthorized},
 		{CodeTokenExpired, http.StatusUnauthorized},
 		{CodePermissionDenied, http.StatusForbidden},
 		{CodeQuotaExceeded, http.StatusForbidden},
+		{CodePreviewPending, http.StatusAccepted},
+		{CodeSSORequired, http.StatusForbidden},
+		{CodeFolderTooDeep, http.StatusConflict},
+		{CodeFolderFull, http.StatusConflict},
+		{CodeDownloadTooLarge, http.StatusForbidden},
 		{ResponseCode(9999), http.StatusInternalServerError}, // unknown code
 	}
 
@@ -452,6 +462,22 @@ func TestParsePageRequest(t *testing.T) {
 	assert.Equal(t, 20, pageReq2.PageSize)    // corrected to default 20
 	assert.Equal(t, "id", pageReq2.SortBy)    // default
 	assert.Equal(t, "desc", pageReq2.SortDir) // corrected from invalid
+	assert.Equal(t, CollationBinary, pageReq2.Collation)
+
+	// 排序规则和语言
+	c, _ = gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/test?collation=locale&locale=zh-CN", nil)
+	pageReq3 := ParsePageRequest(c)
+	assert.Equal(t, CollationLocale, pageReq3.Collation)
+	assert.Equal(t, "zh-CN", pageReq3.Locale)
+
+	// 不支持的排序规则使用字节顺序，未指定语言时使用请求语言
+	c, _ = gin.CreateTestContext(httptest.NewRecorder())
+	c.Set("language", "en-US")
+	c.Request = httptest.NewRequest("GET", "/test?collation=pinyin", nil)
+	pageReq4 := ParsePageRequest(c)
+	assert.Equal(t, CollationBinary, pageReq4.Collation)
+	assert.Equal(t, "en-US", pageReq4.Locale)
 }
 
 func TestPageRequest_Methods(t *testing.T) {
@@ -364,6 +364,20 @@ func StringToBool(s string, defaultValue bool) bool {
 	}
 }
 
+// FormatBytes 将字节数格式化为便于阅读的大小(1024进制，保留一位小数)
+func FormatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	value, suffix := float64(size)/unit, "KMGTPE"
+	i := 0
+	for ; value >= unit && i < len(suffix)-1; i++ {
+		value /= unit
+	}
+	return fmt.Sprintf("%.1f %cB", value, suffix[i])
+}
+
 // JoinNonEmpty 连接非空字符串
 func JoinNonEmpty(sep string, strs ...string) string {
 	var nonEmpty []string
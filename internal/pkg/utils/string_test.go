@@ -611,3 +611,19 @@ func BenchmarkToCamelCase(b *testing.B) {
 		ToCamelCase("hello_world_test_string")
 	}
 }
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		size     int64
+		expected string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KB"},
+		{1536, "1.5 KB"},
+		{10 * 1024 * 1024 * 1024, "10.0 GB"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, FormatBytes(tt.size))
+	}
+}
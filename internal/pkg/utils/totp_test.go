@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"encoding/base32"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rfc6238Secret RFC 6238 附录B测试向量使用的SHA1密钥
+var rfc6238Secret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPCode_RFC6238Vectors(t *testing.T) {
+	// RFC 6238 附录B给出8位验证码，取后6位即为6位验证码
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, tt := range tests {
+		code, err := TOTPCode(rfc6238Secret, TOTPStep(time.Unix(tt.unix, 0)))
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, code, "unix=%d", tt.unix)
+	}
+
+	_, err := TOTPCode("not base32!", 1)
+	assert.Error(t, err)
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := TOTPStep(now)
+	previous, err := TOTPCode(rfc6238Secret, step-1)
+	require.NoError(t, err)
+	stale, err := TOTPCode(rfc6238Secret, step-2)
+	require.NoError(t, err)
+
+	matched, ok := ValidateTOTP(rfc6238Secret, "050471", now, 1)
+	assert.True(t, ok)
+	assert.Equal(t, step, matched)
+
+	// 允许一个时间步的时钟偏差
+	matched, ok = ValidateTOTP(rfc6238Secret, previous, now, 1)
+	assert.True(t, ok)
+	assert.Equal(t, step-1, matched)
+
+	_, ok = ValidateTOTP(rfc6238Secret, stale, now, 1)
+	assert.False(t, ok)
+	_, ok = ValidateTOTP(rfc6238Secret, "12345", now, 1)
+	assert.False(t, ok)
+}
+
+func TestGenerateTOTPSecretAndURI(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	require.NoError(t, err)
+	assert.Len(t, secret, 32)
+	_, err = TOTPCode(secret, 1)
+	assert.NoError(t, err)
+
+	uri, err := url.Parse(TOTPProvisioningURI("Cloud Pan", "user@example.com", secret))
+	require.NoError(t, err)
+	assert.Equal(t, "otpauth", uri.Scheme)
+	assert.Equal(t, "totp", uri.Host)
+	assert.Equal(t, "/Cloud Pan:user@example.com", uri.Path)
+	assert.Equal(t, secret, uri.Query().Get("secret"))
+	assert.Equal(t, "Cloud Pan", uri.Query().Get("issuer"))
+	assert.Equal(t, "6", uri.Query().Get("digits"))
+}
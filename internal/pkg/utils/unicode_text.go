@@ -0,0 +1,292 @@
+package utils
+
+import (
+	"strings"
+	"sync"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// 零宽和双向控制字符
+const (
+	runeZeroWidthNonJoiner = '\u200C' // ZWNJ
+	runeZeroWidthJoiner    = '\u200D' // ZWJ
+)
+
+// isInvisibleControl 判断是否为需要去除的零宽/双向控制字符
+func isInvisibleControl(r rune) bool {
+	switch {
+	case r == '\u00AD': // 软连字符
+		return true
+	case r == '\u061C': // 阿拉伯字母标记
+		return true
+	case r == '\u180E': // 蒙古文元音分隔符
+		return true
+	case r >= '\u200B' && r <= '\u200F': // 零宽空格、ZWNJ、ZWJ、LRM、RLM
+		return true
+	case r >= '\u202A' && r <= '\u202E': // 双向嵌入和覆盖
+		return true
+	case r >= '\u2060' && r <= '\u2064': // 词连接符和不可见运算符
+		return true
+	case r >= '\u2066' && r <= '\u2069': // 双向隔离
+		return true
+	case r == '\uFEFF': // 字节序标记/零宽不换行空格
+		return true
+	}
+	return false
+}
+
+// isExtendedPictographic 近似判断是否为emoji字符
+func isExtendedPictographic(r rune) bool {
+	switch {
+	case r >= 0x1F000 && r <= 0x1FAFF:
+		return true
+	case r >= 0x2600 && r <= 0x27BF:
+		return true
+	case r >= 0x2300 && r <= 0x23FF:
+		return true
+	case r >= 0x2B00 && r <= 0x2BFF:
+		return true
+	case r == 0x00A9 || r == 0x00AE || r == 0x203C || r == 0x2049 || r == 0x2122:
+		return true
+	}
+	return false
+}
+
+// isRegionalIndicator 判断是否为区域指示符(国旗emoji的组成部分)
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1F1E6 && r <= 0x1F1FF
+}
+
+// isGraphemeExtend 判断字符是否附着在前一个字素上
+func isGraphemeExtend(r rune) bool {
+	switch {
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc):
+		return true
+	case r >= 0xFE00 && r <= 0xFE0F: // 变体选择符
+		return true
+	case r >= 0xE0100 && r <= 0xE01EF: // 变体选择符补充
+		return true
+	case r >= 0x1F3FB && r <= 0x1F3FF: // emoji肤色修饰符
+		return true
+	case r >= 0xE0020 && r <= 0xE007F: // 标签字符(地区旗帜)
+		return true
+	case r >= 0x1160 && r <= 0x11FF: // 谚文中声和终声
+		return true
+	}
+	return false
+}
+
+// GraphemeCount 按用户感知的字符(字素簇)计算长度
+//
+// 组合字符、emoji修饰符、ZWJ连接的emoji序列和国旗都计为一个字符，
+// 实现为 UAX #29 的简化版本，覆盖名称类字段的常见情况
+func GraphemeCount(s string) int {
+	count := 0
+	var base rune        // 当前字素的基础字符
+	afterJoiner := false // 上一个字符是否为emoji序列中的ZWJ
+	regionalRun := 0     // 连续区域指示符数量
+
+	for i, r := range s {
+		switch {
+		case i == 0:
+			count++
+		case r == runeZeroWidthJoiner || isGraphemeExtend(r):
+			// 附着在前一个字素上
+		case afterJoiner && isExtendedPictographic(r):
+			// ZWJ连接的emoji序列
+		case base == '\r' && r == '\n':
+			// CRLF
+		case isRegionalIndicator(r) && regionalRun%2 == 1:
+			// 两个区域指示符组成一面旗帜
+		default:
+			count++
+		}
+
+		afterJoiner = r == runeZeroWidthJoiner && isExtendedPictographic(base)
+		if isRegionalIndicator(r) {
+			regionalRun++
+		} else if !isGraphemeExtend(r) {
+			regionalRun = 0
+		}
+		if r != runeZeroWidthJoiner && !isGraphemeExtend(r) {
+			base = r
+		}
+	}
+	return count
+}
+
+// SanitizeDisplayText 规范化用户可见的名称文本
+//
+// 执行NFC规范化，去除零宽和双向控制字符(保留emoji序列中的ZWJ和
+// 连写文字中的ZWNJ)，并去除首尾空白
+func SanitizeDisplayText(s string) string {
+	runes := []rune(norm.NFC.String(s))
+	var b strings.Builder
+	b.Grow(len(s))
+
+	for i, r := range runes {
+		if isInvisibleControl(r) {
+			var before, after rune
+			if i > 0 {
+				before = runes[i-1]
+			}
+			if i+1 < len(runes) {
+				after = runes[i+1]
+			}
+			if !keepJoiner(r, before, after) {
+				continue
+			}
+		}
+		b.WriteRune(r)
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// keepJoiner 判断连接控制符在上下文中是否有意义
+func keepJoiner(r, before, after rune) bool {
+	switch r {
+	case runeZeroWidthJoiner:
+		return (isExtendedPictographic(before) || isGraphemeExtend(before)) && isExtendedPictographic(after)
+	case runeZeroWidthNonJoiner:
+		return unicode.IsLetter(before) && unicode.IsLetter(after) &&
+			!unicode.Is(unicode.Latin, before) && !unicode.Is(unicode.Latin, after)
+	}
+	return false
+}
+
+// confusableMap 常见易混淆字符到拉丁字母的映射(参考 UTS #39 confusables)
+var confusableMap = map[rune]rune{
+	// 西里尔字母
+	'а': 'a', 'в': 'b', 'е': 'e', 'ё': 'e', 'к': 'k', 'м': 'm', 'н': 'h', 'о': 'o',
+	'р': 'p', 'с': 'c', 'т': 't', 'у': 'y', 'х': 'x', 'ѕ': 's', 'і': 'i', 'ј': 'j',
+	'ԁ': 'd', 'ɡ': 'g', 'һ': 'h', 'ӏ': 'l', 'ԛ': 'q', 'ԝ': 'w',
+	// 希腊字母
+	'α': 'a', 'β': 'b', 'ε': 'e', 'η': 'n', 'ι': 'i', 'κ': 'k', 'ν': 'v', 'ο': 'o',
+	'ρ': 'p', 'τ': 't', 'υ': 'u', 'χ': 'x', 'γ': 'y',
+	// 数字和符号
+	'0': 'o', '1': 'l', '|': 'l', 'ı': 'i', 'ǀ': 'l',
+}
+
+// ConfusableSkeleton 计算文本的易混淆骨架
+//
+// 两个文本骨架相同表示视觉上容易混淆(如拉丁字母a和西里尔字母а、admin和аdmin)。
+// 计算过程：NFKD兼容分解(全角转半角等)、去除零宽字符和组合附加符号、小写化、同形字映射
+func ConfusableSkeleton(s string) string {
+	s = norm.NFKD.String(SanitizeDisplayText(s))
+
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range s {
+		if unicode.Is(unicode.Mn, r) || isInvisibleControl(r) {
+			continue
+		}
+		// 大写I与小写l形状相同，需要在小写化之前处理
+		if r == 'I' {
+			r = 'l'
+		}
+		r = unicode.ToLower(r)
+		if mapped, ok := confusableMap[r]; ok {
+			r = mapped
+		}
+		b.WriteRune(r)
+	}
+
+	skeleton := b.String()
+	skeleton = strings.ReplaceAll(skeleton, "rn", "m")
+	skeleton = strings.ReplaceAll(skeleton, "vv", "w")
+	return skeleton
+}
+
+// IsConfusable 判断两个文本是否视觉上易混淆(骨架相同)
+func IsConfusable(a, b string) bool {
+	return ConfusableSkeleton(a) == ConfusableSkeleton(b)
+}
+
+// HasMixedScriptWord 检测是否有单词混用了拉丁、西里尔、希腊字母
+//
+// 同一个单词混用这些文字几乎只出现在仿冒场景(如"Pаypal"中的西里尔字母а)；
+// 中文、日文等与拉丁字母混排属于正常用法，不视为混用
+func HasMixedScriptWord(s string) bool {
+	for _, word := range strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.Is(unicode.Mn, r)
+	}) {
+		scripts := 0
+		for _, table := range []*unicode.RangeTable{unicode.Latin, unicode.Cyrillic, unicode.Greek} {
+			if strings.IndexFunc(word, func(r rune) bool { return unicode.Is(table, r) }) >= 0 {
+				scripts++
+			}
+		}
+		if scripts > 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// ProfanityFilter 敏感词过滤器
+//
+// 匹配基于易混淆骨架并忽略空白和标点，可以识别"b.a.d"、全角字符、
+// 同形字替换等常见的绕过方式
+type ProfanityFilter struct {
+	words []string
+}
+
+// NewProfanityFilter 创建敏感词过滤器
+func NewProfanityFilter(words []string) *ProfanityFilter {
+	filter := &ProfanityFilter{}
+	seen := make(map[string]bool)
+	for _, word := range words {
+		normalized := compactSkeleton(word)
+		if normalized == "" || seen[normalized] {
+			continue
+		}
+		seen[normalized] = true
+		filter.words = append(filter.words, normalized)
+	}
+	return filter
+}
+
+// Match 返回文本命中的第一个敏感词
+func (f *ProfanityFilter) Match(text string) (string, bool) {
+	if f == nil || len(f.words) == 0 {
+		return "", false
+	}
+	normalized := compactSkeleton(text)
+	for _, word := range f.words {
+		if strings.Contains(normalized, word) {
+			return word, true
+		}
+	}
+	return "", false
+}
+
+// compactSkeleton 计算骨架并去除非字母数字字符
+func compactSkeleton(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			return r
+		}
+		return -1
+	}, ConfusableSkeleton(s))
+}
+
+var (
+	profanityFilterMu sync.RWMutex
+	profanityFilter   *ProfanityFilter
+)
+
+// SetProfanityFilter 设置全局敏感词过滤器，传入nil表示关闭过滤
+func SetProfanityFilter(filter *ProfanityFilter) {
+	profanityFilterMu.Lock()
+	defer profanityFilterMu.Unlock()
+	profanityFilter = filter
+}
+
+// GetProfanityFilter 获取全局敏感词过滤器，未启用时返回nil
+func GetProfanityFilter() *ProfanityFilter {
+	profanityFilterMu.RLock()
+	defer profanityFilterMu.RUnlock()
+	return profanityFilter
+}
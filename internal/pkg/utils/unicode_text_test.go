@@ -0,0 +1,134 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGraphemeCount(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected int
+	}{
+		{"ascii", "hello", 5},
+		{"chinese", "张三", 2},
+		{"combining accent", "e\u0301", 1},
+		{"emoji with skin tone", "\U0001F44D\U0001F3FD", 1},
+		{"zwj family", "\U0001F468\u200D\U0001F469\u200D\U0001F467", 1},
+		{"emoji with variation selector", "❤\uFE0F", 1},
+		{"flags", "\U0001F1E8\U0001F1F3\U0001F1FA\U0001F1F8", 2},
+		{"mixed", "Tom\U0001F600张", 5},
+		{"empty", "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, GraphemeCount(tt.input))
+		})
+	}
+}
+
+func TestSanitizeDisplayText(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"zero width space", "ad\u200Bmin", "admin"},
+		{"bidi override", "\u202Egpj.exe", "gpj.exe"},
+		{"bidi isolate", "\u2066name\u2069", "name"},
+		{"bom and trim", "\uFEFF  John  ", "John"},
+		{"keeps emoji zwj", "\U0001F468\u200D\U0001F469", "\U0001F468\u200D\U0001F469"},
+		{"drops stray zwj", "a\u200Db", "ab"},
+		{"keeps persian zwnj", "می\u200Cخواهم", "می\u200Cخواهم"},
+		{"nfc", "e\u0301", "é"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, SanitizeDisplayText(tt.input))
+		})
+	}
+}
+
+func TestConfusableSkeleton(t *testing.T) {
+	assert.True(t, IsConfusable("admin", "аdmin"))   // 西里尔字母а
+	assert.True(t, IsConfusable("paypal", "раypal")) // 西里尔字母р、а
+	assert.True(t, IsConfusable("root", "r00t"))
+	assert.True(t, IsConfusable("Support", "Ｓupport")) // 全角S
+	assert.True(t, IsConfusable("modern", "modem"))    // rn与m
+	assert.True(t, IsConfusable("ad\u200Bmin", "admin"))
+	assert.False(t, IsConfusable("alice", "bob"))
+}
+
+func TestHasMixedScriptWord(t *testing.T) {
+	assert.True(t, HasMixedScriptWord("Pаypal"))
+	assert.True(t, HasMixedScriptWord("hello вorld"))
+	assert.False(t, HasMixedScriptWord("Привет world"))
+	assert.False(t, HasMixedScriptWord("张三Tom"))
+	assert.False(t, HasMixedScriptWord("John Doe"))
+}
+
+func TestProfanityFilter(t *testing.T) {
+	filter := NewProfanityFilter([]string{"badword", "", "BadWord"})
+
+	tests := []struct {
+		input   string
+		matched bool
+	}{
+		{"badword", true},
+		{"xBADWORDx", true},
+		{"b.a.d-w_o_r_d", true},
+		{"ｂadword", true}, // 全角b
+		{"bаdwоrd", true}, // 西里尔字母а、о
+		{"goodword", false},
+	}
+	for _, tt := range tests {
+		_, matched := filter.Match(tt.input)
+		assert.Equal(t, tt.matched, matched, tt.input)
+	}
+
+	var nilFilter *ProfanityFilter
+	_, matched := nilFilter.Match("badword")
+	assert.False(t, matched)
+}
+
+func TestValidateNamesWithUnicode(t *testing.T) {
+	validator := NewValidator()
+
+	t.Run("显示名称按字素计算长度", func(t *testing.T) {
+		assert.NoError(t, validator.ValidateDisplayName(strings.Repeat("\U0001F468\u200D\U0001F469\u200D\U0001F467", 100)))
+		assert.Error(t, validator.ValidateDisplayName(strings.Repeat("\U0001F600", 101)))
+		assert.NoError(t, validator.ValidateDisplayName("Tom \U0001F44D\U0001F3FD"))
+	})
+
+	t.Run("显示名称忽略不可见字符", func(t *testing.T) {
+		assert.Error(t, validator.ValidateDisplayName("\u200B\u200B"))
+		assert.Error(t, validator.ValidateDisplayName("Pаypal Support"))
+	})
+
+	t.Run("用户名易混淆保留名称", func(t *testing.T) {
+		assert.Error(t, validator.ValidateUsername("r00t"))
+		assert.Error(t, validator.ValidateUsername("adrnin"))
+	})
+
+	t.Run("敏感词过滤", func(t *testing.T) {
+		SetProfanityFilter(NewProfanityFilter([]string{"badword"}))
+		defer SetProfanityFilter(nil)
+
+		assert.Error(t, validator.ValidateUsername("the_badword"))
+		assert.Error(t, validator.ValidateTeamName("Bad Word Team"))
+		assert.NoError(t, validator.ValidateTeamName("研发团队 \U0001F680"))
+		assert.NoError(t, validator.ValidateUsername("gooduser"))
+	})
+
+	t.Run("团队名称", func(t *testing.T) {
+		assert.Error(t, ValidateTeamName(""))
+		assert.Error(t, ValidateTeamName("a"))
+		assert.Error(t, ValidateTeamName(strings.Repeat("团", 51)))
+		assert.NoError(t, ValidateTeamName("Core Team"))
+	})
+}
@@ -18,6 +18,7 @@ type Validator interface {
 	ValidateEmail(email string) error
 	ValidateUsername(username string) error
 	ValidateDisplayName(name string) error
+	ValidateTeamName(name string) error
 	ValidateRequired(value, fieldName string) error
 	ValidateLength(value string, min, max int, fieldName string) error
 	ValidatePattern(value, pattern, fieldName string) error
@@ -158,6 +159,11 @@ func (v *defaultValidator) ValidateEmail(email string) error {
 	return validateEmailDomain(email)
 }
 
+// ReservedUsernames 返回保留用户名列表的副本，供缓存预热等模块使用
+func ReservedUsernames() []string {
+	return getReservedUsernames()
+}
+
 // getReservedUsernames 获取保留用户名列表
 func getReservedUsernames() []string {
 	return []string{
@@ -204,17 +210,57 @@ func validateUsernameConsecutiveChars(username string) error {
 	return nil
 }
 
-// validateUsernameReserved 验证用户名是否为保留名称
+// validateUsernameReserved 验证用户名是否为保留名称(包括视觉上易混淆的变体，如r00t)
 func validateUsernameReserved(username string) error {
 	reservedNames := getReservedUsernames()
 	for _, reserved := range reservedNames {
-		if strings.EqualFold(username, reserved) {
+		if strings.EqualFold(username, reserved) || IsConfusable(username, reserved) {
 			return fmt.Errorf("该用户名为系统保留，不可使用")
 		}
 	}
 	return nil
 }
 
+// validateProfanity 使用全局敏感词过滤器检查文本
+func validateProfanity(value, fieldName string) error {
+	if _, matched := GetProfanityFilter().Match(value); matched {
+		return fmt.Errorf("%s包含不允许使用的词语", fieldName)
+	}
+	return nil
+}
+
+// validateNameText 验证用户可见名称(显示名称、团队名称)的通用规则
+//
+// 名称先经过 SanitizeDisplayText 规范化，长度按字素计算
+func validateNameText(name string, min, max int, fieldName string) error {
+	name = SanitizeDisplayText(name)
+
+	length := GraphemeCount(name)
+	if length < min {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("%s不能全是空白字符", fieldName)
+		}
+		return fmt.Errorf("%s长度不能少于%d个字符", fieldName, min)
+	}
+	if length > max {
+		return fmt.Errorf("%s长度不能超过%d个字符", fieldName, max)
+	}
+
+	// 检查是否包含不允许的字符
+	for _, r := range name {
+		if unicode.IsControl(r) {
+			return fmt.Errorf("%s不能包含控制字符", fieldName)
+		}
+	}
+
+	// 同一单词混用拉丁/西里尔/希腊字母通常用于仿冒
+	if HasMixedScriptWord(name) {
+		return fmt.Errorf("%s不能在同一单词中混用不同文字的字母", fieldName)
+	}
+
+	return nil
+}
+
 // ValidateUsername 验证用户名格式
 func (v *defaultValidator) ValidateUsername(username string) error {
 	if username == "" {
@@ -244,35 +290,37 @@ func (v *defaultValidator) ValidateUsername(username string) error {
 	}
 
 	// 验证保留名称
-	return validateUsernameReserved(username)
+	if err := validateUsernameReserved(username); err != nil {
+		return err
+	}
+
+	// 敏感词过滤
+	return validateProfanity(username, "用户名")
 }
 
 // ValidateDisplayName 验证显示名称
+//
+// 允许任意Unicode(包括emoji)，长度按用户感知的字符计算；
+// 零宽和双向控制字符会被忽略，调用方应保存 SanitizeDisplayText 规范化后的值
 func (v *defaultValidator) ValidateDisplayName(name string) error {
 	if name == "" {
 		return nil // 显示名称可以为空
 	}
 
-	name = strings.TrimSpace(name)
-
-	// 检查长度
-	if err := v.ValidateLength(name, 1, 100, "显示名称"); err != nil {
-		return err
-	}
+	return validateNameText(name, 1, 100, "显示名称")
+}
 
-	// 检查是否包含不允许的字符
-	for _, r := range name {
-		if r < 32 || r == 127 { // 控制字符
-			return fmt.Errorf("显示名称不能包含控制字符")
-		}
+// ValidateTeamName 验证团队名称，规则与显示名称相同并额外进行敏感词过滤
+func (v *defaultValidator) ValidateTeamName(name string) error {
+	if name == "" {
+		return fmt.Errorf("团队名称不能为空")
 	}
 
-	// 不能全是空白字符
-	if strings.TrimSpace(name) == "" {
-		return fmt.Errorf("显示名称不能全是空白字符")
+	if err := validateNameText(name, 2, 50, "团队名称"); err != nil {
+		return err
 	}
 
-	return nil
+	return validateProfanity(name, "团队名称")
 }
 
 // ValidateRequired 验证必填字段
@@ -335,6 +383,11 @@ func ValidateDisplayName(name string) error {
 	return defaultValidatorInstance.ValidateDisplayName(name)
 }
 
+// ValidateTeamName 验证团队名称
+func ValidateTeamName(name string) error {
+	return defaultValidatorInstance.ValidateTeamName(name)
+}
+
 // ValidateRequired 验证必填字段
 func ValidateRequired(value, fieldName string) error {
 	return defaultValidatorInstance.ValidateRequired(value, fieldName)
@@ -444,7 +497,7 @@ func ValidateVerificationCode(code string) error {
 
 // ValidateCodeType 验证验证码类型
 func ValidateCodeType(codeType string) error {
-	validTypes := []string{"register", "password_reset", "login", "change_email"}
+	validTypes := []string{"register", "password_reset", "login", "change_email", "mfa"}
 
 	for _, validType := range validTypes {
 		if codeType == validType {
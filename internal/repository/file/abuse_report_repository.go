@@ -0,0 +1,35 @@
+package file
+
+import (
+	"context"
+	"time"
+
+	"cloudpan/internal/repository/models"
+)
+
+// AbuseReportRepository 公开分享举报数据仓库接口
+//
+// 提供分享举报和审核队列的数据访问操作，包括：
+// 1. 登记：保存访问者提交的举报
+// 2. 查询：按审核状态和分享分页列出举报，统计分享待审核举报的不同举报IP数
+// 3. 审核：将分享的全部待审核举报一起标记为驳回或确认违规
+//
+// 使用示例：
+//
+//	repo := NewAbuseReportRepository(db)
+//	err := repo.Create(ctx, report)
+//	reporters, err := repo.CountPendingReporters(ctx, report.ShareID)
+//	resolved, err := repo.ResolvePending(ctx, shareID, models.ShareAbuseStatusDismissed, adminID, nil, time.Now())
+type AbuseReportRepository interface {
+	// 登记
+	Create(ctx context.Context, report *models.ShareAbuseReport) error
+
+	// 查询
+	GetByID(ctx context.Context, id uint) (*models.ShareAbuseReport, error)
+	HasPending(ctx context.Context, shareID uint, reporterIP string) (bool, error)
+	CountPendingReporters(ctx context.Context, shareID uint) (int64, error)
+	List(ctx context.Context, status string, shareID uint, limit, offset int) ([]*models.ShareAbuseReport, int64, error)
+
+	// 审核
+	ResolvePending(ctx context.Context, shareID uint, status string, reviewedBy uint, note *string, reviewedAt time.Time) (int64, error)
+}
@@ -0,0 +1,108 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/database"
+	"cloudpan/internal/repository/models"
+)
+
+// abuseReportRepository 公开分享举报数据仓库实现
+type abuseReportRepository struct {
+	db *gorm.DB
+}
+
+// NewAbuseReportRepository 创建公开分享举报数据仓库实例
+func NewAbuseReportRepository(db *gorm.DB) AbuseReportRepository {
+	return &abuseReportRepository{
+		db: db,
+	}
+}
+
+// Create 保存举报
+func (r *abuseReportRepository) Create(ctx context.Context, report *models.ShareAbuseReport) error {
+	if report == nil {
+		return fmt.Errorf("举报不能为空")
+	}
+	return database.Conn(ctx, r.db).Create(report).Error
+}
+
+// GetByID 根据ID获取举报
+func (r *abuseReportRepository) GetByID(ctx context.Context, id uint) (*models.ShareAbuseReport, error) {
+	if id == 0 {
+		return nil, fmt.Errorf("举报ID不能为空")
+	}
+
+	var report models.ShareAbuseReport
+	if err := database.Conn(ctx, r.db).First(&report, id).Error; err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// HasPending 检查同一IP对该分享是否已有待审核的举报
+func (r *abuseReportRepository) HasPending(ctx context.Context, shareID uint, reporterIP string) (bool, error) {
+	var count int64
+	err := database.Conn(ctx, r.db).Model(&models.ShareAbuseReport{}).
+		Where("share_id = ? AND status = ? AND reporter_ip = ?", shareID, models.ShareAbuseStatusPending, reporterIP).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// CountPendingReporters 统计分享待审核举报的不同举报IP数
+func (r *abuseReportRepository) CountPendingReporters(ctx context.Context, shareID uint) (int64, error) {
+	var count int64
+	err := database.Conn(ctx, r.db).Model(&models.ShareAbuseReport{}).
+		Where("share_id = ? AND status = ?", shareID, models.ShareAbuseStatusPending).
+		Distinct("reporter_ip").
+		Count(&count).Error
+	return count, err
+}
+
+// List 分页获取举报，按举报时间倒序；status为空时查询全部状态，shareID为0时查询全部分享
+func (r *abuseReportRepository) List(ctx context.Context, status string, shareID uint, limit, offset int) ([]*models.ShareAbuseReport, int64, error) {
+	query := database.Conn(ctx, r.db).Model(&models.ShareAbuseReport{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if shareID != 0 {
+		query = query.Where("share_id = ?", shareID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var reports []*models.ShareAbuseReport
+	err := query.Order("created_at DESC, id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&reports).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return reports, total, nil
+}
+
+// ResolvePending 将分享的全部待审核举报标记为指定的审核状态，返回处理的举报数
+func (r *abuseReportRepository) ResolvePending(ctx context.Context, shareID uint, status string, reviewedBy uint, note *string, reviewedAt time.Time) (int64, error) {
+	if shareID == 0 {
+		return 0, fmt.Errorf("分享ID不能为空")
+	}
+
+	result := database.Conn(ctx, r.db).Model(&models.ShareAbuseReport{}).
+		Where("share_id = ? AND status = ?", shareID, models.ShareAbuseStatusPending).
+		UpdateColumns(map[string]interface{}{
+			"status":      status,
+			"reviewed_by": reviewedBy,
+			"reviewed_at": reviewedAt,
+			"review_note": note,
+			"updated_at":  reviewedAt,
+		})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,66 @@
+package file
+
+import (
+	"context"
+	"time"
+
+	basemodels "cloudpan/internal/pkg/database/models"
+	"cloudpan/internal/repository/models"
+)
+
+// FileRepository 文件数据仓库接口
+//
+// 提供文件和文件夹相关的数据访问操作，包括：
+// 1. 文件查询：按ID、UUID查询，列出子项
+// 2. 完整性校验：维护文件夹组合校验和
+// 3. 上传登记：创建待上传文件记录，上传完成后激活
+// 4. 归档存储：查询归档候选文件，记录归档和恢复状态
+// 5. 访问统计：记录下载次数和最后访问时间
+// 6. 文件预览：记录生成的缩略图和预览图地址
+// 7. 文件标签：更新文件的标签
+// 8. 文件属性：更新文件的元数据
+//
+// 使用示例：
+//
+//	repo := NewFileRepository(db)
+//	folder, err := repo.GetByID(ctx, folderID)
+//	children, err := repo.ListChildren(ctx, folder.ID)
+type FileRepository interface {
+	// 基础查询
+	GetByID(ctx context.Context, id uint) (*models.File, error)
+	GetByUUID(ctx context.Context, uuid string) (*models.File, error)
+	ListChildren(ctx context.Context, parentID uint) ([]*models.File, error)
+
+	// 文件夹校验和
+	UpdateChecksum(ctx context.Context, id uint, checksum string, computedAt time.Time) error
+	ClearChecksums(ctx context.Context, ids []uint) error
+
+	// 上传登记
+	Create(ctx context.Context, file *models.File) error
+	CompleteUpload(ctx context.Context, id uint, size int64) (bool, error)
+	FailUpload(ctx context.Context, id uint) error
+	UpdateUploadMetadata(ctx context.Context, id uint, metadata *basemodels.JSONMap) error
+	DeleteUpload(ctx context.Context, file *models.File) (bool, error)
+	ListStaleUploads(ctx context.Context, createdBefore time.Time, limit int) ([]*models.File, error)
+
+	// 归档存储
+	ListArchiveCandidates(ctx context.Context, inactiveBefore time.Time, minSize, maxSize int64, limit int) ([]*models.File, error)
+	MarkArchived(ctx context.Context, id uint, storageClass string, archivedAt time.Time) error
+	UpdateRestoreState(ctx context.Context, id uint, requestedAt, restoredUntil *time.Time) error
+
+	// 访问统计
+	RecordDownload(ctx context.Context, id uint, at time.Time) error
+
+	// 文件预览
+	UpdatePreviewURLs(ctx context.Context, id uint, thumbnailURL, previewURL *string) error
+
+	// 处理流水线状态
+	UpdateScanStatus(ctx context.Context, id uint, status string) error
+	UpdatePreviewStatus(ctx context.Context, id uint, status string) error
+
+	// 文件标签
+	UpdateTags(ctx context.Context, id uint, tags *string) error
+
+	// 文件属性
+	UpdateMetadata(ctx context.Context, id uint, metadata *basemodels.JSONMap) error
+}
@@ -0,0 +1,329 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/database"
+	basemodels "cloudpan/internal/pkg/database/models"
+	"cloudpan/internal/repository/models"
+)
+
+// fileRepository 文件数据仓库实现
+type fileRepository struct {
+	db *gorm.DB
+}
+
+// NewFileRepository 创建文件数据仓库实例
+func NewFileRepository(db *gorm.DB) FileRepository {
+	return &fileRepository{
+		db: db,
+	}
+}
+
+// GetByID 根据ID获取文件
+func (r *fileRepository) GetByID(ctx context.Context, id uint) (*models.File, error) {
+	if id == 0 {
+		return nil, fmt.Errorf("文件ID不能为空")
+	}
+
+	var file models.File
+	err := database.Conn(ctx, r.db).First(&file, id).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &file, nil
+}
+
+// GetByUUID 根据UUID获取文件
+func (r *fileRepository) GetByUUID(ctx context.Context, uuid string) (*models.File, error) {
+	if uuid == "" {
+		return nil, fmt.Errorf("文件UUID不能为空")
+	}
+
+	var file models.File
+	err := database.Conn(ctx, r.db).Where("uuid = ?", uuid).First(&file).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &file, nil
+}
+
+// ListChildren 获取文件夹下的直接子项
+func (r *fileRepository) ListChildren(ctx context.Context, parentID uint) ([]*models.File, error) {
+	if parentID == 0 {
+		return nil, fmt.Errorf("父文件夹ID不能为空")
+	}
+
+	var children []*models.File
+	err := database.Conn(ctx, r.db).
+		Where("parent_id = ?", parentID).
+		Order("name ASC").
+		Find(&children).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return children, nil
+}
+
+// UpdateChecksum 保存文件夹组合校验和
+func (r *fileRepository) UpdateChecksum(ctx context.Context, id uint, checksum string, computedAt time.Time) error {
+	if id == 0 {
+		return fmt.Errorf("文件ID不能为空")
+	}
+
+	// 使用UpdateColumns避免触发版本号自增
+	return database.Conn(ctx, r.db).Model(&models.File{}).
+		Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{
+			"content_checksum":    checksum,
+			"checksum_updated_at": computedAt,
+		}).Error
+}
+
+// ClearChecksums 清除文件夹校验和，标记为需要重新计算
+func (r *fileRepository) ClearChecksums(ctx context.Context, ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	return database.Conn(ctx, r.db).Model(&models.File{}).
+		Where("id IN ?", ids).
+		UpdateColumns(map[string]interface{}{
+			"content_checksum":    nil,
+			"checksum_updated_at": nil,
+		}).Error
+}
+
+// Create 创建文件记录
+func (r *fileRepository) Create(ctx context.Context, file *models.File) error {
+	if file == nil {
+		return fmt.Errorf("文件不能为空")
+	}
+
+	return database.Conn(ctx, r.db).Create(file).Error
+}
+
+// CompleteUpload 将上传中的文件标记为可用，返回是否由本次调用完成
+//
+// 条件更新保证并发或重复的完成回调只有一次生效，调用方据此决定是否累计存储用量
+func (r *fileRepository) CompleteUpload(ctx context.Context, id uint, size int64) (bool, error) {
+	if id == 0 {
+		return false, fmt.Errorf("文件ID不能为空")
+	}
+
+	result := database.Conn(ctx, r.db).Model(&models.File{}).
+		Where("id = ? AND status = ?", id, "uploading").
+		UpdateColumns(map[string]interface{}{
+			"size":          size,
+			"status":        "active",
+			"upload_status": "completed",
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// FailUpload 将上传中的文件标记为失败
+func (r *fileRepository) FailUpload(ctx context.Context, id uint) error {
+	if id == 0 {
+		return fmt.Errorf("文件ID不能为空")
+	}
+
+	return database.Conn(ctx, r.db).Model(&models.File{}).
+		Where("id = ? AND status = ?", id, "uploading").
+		UpdateColumns(map[string]interface{}{
+			"status":        "error",
+			"upload_status": "failed",
+		}).Error
+}
+
+// UpdateUploadMetadata 更新上传中文件记录的分片规划元数据，上传已结束时不修改
+func (r *fileRepository) UpdateUploadMetadata(ctx context.Context, id uint, metadata *basemodels.JSONMap) error {
+	if id == 0 {
+		return fmt.Errorf("文件ID不能为空")
+	}
+
+	return database.Conn(ctx, r.db).Model(&models.File{}).
+		Where("id = ? AND status = ?", id, "uploading").
+		UpdateColumn("metadata", metadata).Error
+}
+
+// DeleteUpload 物理删除仍在上传中的文件记录，返回是否删除
+//
+// 只删除状态为上传中的记录，已完成或已失败的上传不受影响；删除时同时登记UUID墓碑
+func (r *fileRepository) DeleteUpload(ctx context.Context, file *models.File) (bool, error) {
+	if file == nil || file.ID == 0 {
+		return false, fmt.Errorf("文件ID不能为空")
+	}
+
+	deleted := false
+	err := database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().Where("status = ?", "uploading").Delete(file)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			// 回滚删除钩子写入的墓碑
+			return errUploadNotDeleted
+		}
+		deleted = true
+		return nil
+	})
+	if errors.Is(err, errUploadNotDeleted) {
+		return false, nil
+	}
+	return deleted, err
+}
+
+// errUploadNotDeleted 上传记录已不在上传中，回滚删除事务
+var errUploadNotDeleted = errors.New("upload is no longer in progress")
+
+// ListStaleUploads 查询创建时间早于createdBefore、仍在分片上传中的文件记录
+func (r *fileRepository) ListStaleUploads(ctx context.Context, createdBefore time.Time, limit int) ([]*models.File, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("查询数量必须大于0")
+	}
+
+	var files []*models.File
+	err := database.Conn(ctx, r.db).
+		Where("status = ? AND upload_status = ? AND created_at < ?", "uploading", "uploading", createdBefore).
+		Order("id").
+		Limit(limit).
+		Find(&files).Error
+	return files, err
+}
+
+// ListArchiveCandidates 查询长期未访问、可以归档的对象存储文件
+//
+// 从未访问过的文件以最后修改时间为准
+func (r *fileRepository) ListArchiveCandidates(ctx context.Context, inactiveBefore time.Time, minSize, maxSize int64, limit int) ([]*models.File, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("查询数量必须大于0")
+	}
+
+	var files []*models.File
+	err := database.Conn(ctx, r.db).
+		Where("archived_at IS NULL AND status = ? AND is_folder = ? AND storage_type <> ?", "active", false, "local").
+		Where("storage_path IS NOT NULL AND size >= ? AND size <= ?", minSize, maxSize).
+		Where("COALESCE(last_accessed_at, updated_at) < ?", inactiveBefore).
+		Order("id ASC").
+		Limit(limit).
+		Find(&files).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// MarkArchived 记录文件已转换为归档存储，并清除旧的恢复状态
+func (r *fileRepository) MarkArchived(ctx context.Context, id uint, storageClass string, archivedAt time.Time) error {
+	if id == 0 {
+		return fmt.Errorf("文件ID不能为空")
+	}
+
+	return database.Conn(ctx, r.db).Model(&models.File{}).
+		Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{
+			"storage_class":        storageClass,
+			"archived_at":          archivedAt,
+			"restore_requested_at": nil,
+			"restored_until":       nil,
+		}).Error
+}
+
+// UpdateRestoreState 保存归档文件的恢复发起时间和恢复截止时间
+func (r *fileRepository) UpdateRestoreState(ctx context.Context, id uint, requestedAt, restoredUntil *time.Time) error {
+	if id == 0 {
+		return fmt.Errorf("文件ID不能为空")
+	}
+
+	return database.Conn(ctx, r.db).Model(&models.File{}).
+		Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{
+			"restore_requested_at": requestedAt,
+			"restored_until":       restoredUntil,
+		}).Error
+}
+
+// RecordDownload 下载次数加1并更新最后访问时间
+func (r *fileRepository) RecordDownload(ctx context.Context, id uint, at time.Time) error {
+	if id == 0 {
+		return fmt.Errorf("文件ID不能为空")
+	}
+
+	// 使用UpdateColumns避免触发版本号自增，并发下载时由数据库原子累加
+	return database.Conn(ctx, r.db).Model(&models.File{}).
+		Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{
+			"download_count":   gorm.Expr("download_count + ?", 1),
+			"last_accessed_at": at,
+		}).Error
+}
+
+// UpdatePreviewURLs 保存缩略图和预览图地址，不改变文件的版本号和修改时间
+func (r *fileRepository) UpdatePreviewURLs(ctx context.Context, id uint, thumbnailURL, previewURL *string) error {
+	if id == 0 {
+		return fmt.Errorf("文件ID不能为空")
+	}
+
+	return database.Conn(ctx, r.db).Model(&models.File{}).
+		Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{
+			"thumbnail_url": thumbnailURL,
+			"preview_url":   previewURL,
+		}).Error
+}
+
+// UpdateScanStatus 更新病毒扫描状态，不改变文件的版本号和修改时间
+func (r *fileRepository) UpdateScanStatus(ctx context.Context, id uint, status string) error {
+	if id == 0 {
+		return fmt.Errorf("文件ID不能为空")
+	}
+
+	return database.Conn(ctx, r.db).Model(&models.File{}).
+		Where("id = ?", id).
+		UpdateColumn("scan_status", status).Error
+}
+
+// UpdatePreviewStatus 更新预览生成状态，不改变文件的版本号和修改时间
+func (r *fileRepository) UpdatePreviewStatus(ctx context.Context, id uint, status string) error {
+	if id == 0 {
+		return fmt.Errorf("文件ID不能为空")
+	}
+
+	return database.Conn(ctx, r.db).Model(&models.File{}).
+		Where("id = ?", id).
+		UpdateColumn("preview_status", status).Error
+}
+
+// UpdateMetadata 保存文件元数据，不改变文件的版本号和修改时间
+func (r *fileRepository) UpdateMetadata(ctx context.Context, id uint, metadata *basemodels.JSONMap) error {
+	if id == 0 {
+		return fmt.Errorf("文件ID不能为空")
+	}
+
+	return database.Conn(ctx, r.db).Model(&models.File{}).
+		Where("id = ?", id).
+		UpdateColumn("metadata", metadata).Error
+}
+
+// UpdateTags 保存文件标签(逗号分隔)，不改变文件的版本号和修改时间
+func (r *fileRepository) UpdateTags(ctx context.Context, id uint, tags *string) error {
+	if id == 0 {
+		return fmt.Errorf("文件ID不能为空")
+	}
+
+	return database.Conn(ctx, r.db).Model(&models.File{}).
+		Where("id = ?", id).
+		UpdateColumn("tags", tags).Error
+}
@@ -0,0 +1,84 @@
+package file
+
+import (
+	"context"
+
+	"cloudpan/internal/repository/models"
+)
+
+// FolderRepository 文件夹树数据仓库接口
+//
+// 提供文件夹树结构相关的数据访问操作，包括：
+// 1. 子树查询：查询未删除的文件及其全部子项，检查同名文件
+// 2. 移动和重命名：在一个事务中写入根项目的新位置和全部子项的新路径
+// 3. 复制：在一个事务中按父子顺序创建复制出的文件记录
+// 4. 文件夹浏览：按列分页查询文件夹的直接子项，或读取全部子项名称由调用方按自然/语言规则排序
+// 5. 层级和子项数统计：统计文件夹的直接子项数，查询子项数或嵌套层级接近上限的文件夹
+//
+// 使用示例：
+//
+//	repo := NewFolderRepository(db)
+//	files, err := repo.ListSubtree(ctx, folder)
+//	err = repo.MoveTree(ctx, folder, files[1:])
+type FolderRepository interface {
+	// 子树查询
+	ListSubtree(ctx context.Context, root *models.File) ([]*models.File, error)
+	NameExists(ctx context.Context, userID uint, parentID *uint, name string, excludeID uint) (bool, error)
+
+	// 移动和重命名
+	MoveTree(ctx context.Context, root *models.File, descendants []*models.File) error
+
+	// 复制
+	CreateTree(ctx context.Context, files []*models.File, parents []int) error
+
+	// 文件夹浏览
+	ListContents(ctx context.Context, userID uint, parentID *uint, filter ContentsFilter, order ContentsOrder, offset, limit int) ([]*models.File, int64, error)
+	ListContentNames(ctx context.Context, userID uint, parentID *uint, filter ContentsFilter, limit int) ([]*models.File, error)
+	FindContents(ctx context.Context, userID uint, ids []uint, includeUploading bool) ([]*models.File, error)
+
+	// 层级和子项数统计
+	CountChildren(ctx context.Context, userID uint, parentID *uint) (int64, error)
+	ListCrowdedFolders(ctx context.Context, minChildren int64, limit int) ([]FolderChildCount, error)
+	ListDeepFolders(ctx context.Context, minDepth, limit int) ([]*models.File, error)
+}
+
+// FolderChildCount 文件夹的直接子项数
+type FolderChildCount struct {
+	UserID   uint  // 所属用户ID
+	ParentID *uint // 文件夹ID，nil表示用户的根目录
+	Children int64 // 未删除的直接子项数(含上传中的文件)
+}
+
+// 文件夹内容排序列
+const (
+	ContentsOrderName      = "name"
+	ContentsOrderSize      = "size"
+	ContentsOrderCreatedAt = "created_at"
+	ContentsOrderUpdatedAt = "updated_at"
+)
+
+// ContentsFilter 文件夹内容筛选条件，零值不筛选
+//
+// 按处理状态筛选时只返回文件，文件夹没有处理流程
+//
+// IncludeUploading 为true时同时返回进行中的分片上传占位记录(models.File.IsUploadPlaceholder)
+//
+// Tags 按文件所有者添加的标签筛选(不区分文件和文件夹)：默认要求带有全部标签，MatchAnyTag 为true时带有任一标签即可
+type ContentsFilter struct {
+	ScanStatus       string   // 病毒扫描状态(models.ScanStatusClean等)
+	PreviewStatus    string   // 预览生成状态(models.PreviewStatusReady等)，models.PreviewStatusNone 表示不生成预览
+	IncludeUploading bool     // 是否包含上传占位记录
+	Tags             []string // 标签
+	MatchAnyTag      bool     // 是否带有任一标签即可
+}
+
+// IsZero 是否没有任何处理状态筛选条件
+func (f ContentsFilter) IsZero() bool {
+	return f.ScanStatus == "" && f.PreviewStatus == ""
+}
+
+// ContentsOrder 文件夹内容排序方式，文件夹始终排在文件之前
+type ContentsOrder struct {
+	Column string // 排序列，不支持的列按名称排序
+	Desc   bool   // 是否降序
+}
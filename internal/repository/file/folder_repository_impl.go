@@ -0,0 +1,280 @@
+package file
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"cloudpan/internal/pkg/database"
+	"cloudpan/internal/repository/models"
+)
+
+// folderRepository 文件夹树数据仓库实现
+type folderRepository struct {
+	db *gorm.DB
+}
+
+// NewFolderRepository 创建文件夹树数据仓库实例
+func NewFolderRepository(db *gorm.DB) FolderRepository {
+	return &folderRepository{
+		db: db,
+	}
+}
+
+// ListSubtree 获取未删除的文件及其全部子项，按路径长度排序保证父项在子项之前
+//
+// 子项按路径前缀匹配：文件夹 /a/b 的子项路径为 /a/b 或以 /a/b/ 开头
+func (r *folderRepository) ListSubtree(ctx context.Context, root *models.File) ([]*models.File, error) {
+	if root == nil || root.ID == 0 {
+		return nil, fmt.Errorf("文件不能为空")
+	}
+	if !root.IsFolder {
+		return []*models.File{root}, nil
+	}
+
+	fullPath := root.GetFullPath()
+	var files []*models.File
+	err := database.Conn(ctx, r.db).
+		Where("user_id = ?", root.UserID).
+		Where("id = ? OR path = ? OR path LIKE ? ESCAPE '!'", root.ID, fullPath, escapeLike(fullPath)+"/%").
+		Order("LENGTH(path) ASC, id ASC").
+		Find(&files).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// NameExists 检查目标文件夹下是否已有同名的未删除文件，parentID为nil表示根目录
+//
+// excludeID 不为0时排除该文件，用于重命名时忽略文件自身
+func (r *folderRepository) NameExists(ctx context.Context, userID uint, parentID *uint, name string, excludeID uint) (bool, error) {
+	query := database.Conn(ctx, r.db).Model(&models.File{}).
+		Where("user_id = ? AND name = ?", userID, name)
+	if parentID == nil {
+		query = query.Where("parent_id IS NULL")
+	} else {
+		query = query.Where("parent_id = ?", *parentID)
+	}
+	if excludeID != 0 {
+		query = query.Where("id <> ?", excludeID)
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// MoveTree 在一个事务中写入根项目的名称、扩展名、父文件夹和路径，以及全部子项的新路径
+//
+// 新位置由调用方计算；逐条更新以触发更新钩子，保证搜索索引中的路径同步更新
+func (r *folderRepository) MoveTree(ctx context.Context, root *models.File, descendants []*models.File) error {
+	if root == nil || root.ID == 0 {
+		return fmt.Errorf("文件不能为空")
+	}
+
+	return database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(root).Updates(map[string]interface{}{
+			"name":      root.Name,
+			"extension": root.Extension,
+			"parent_id": root.ParentID,
+			"path":      root.Path,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		for _, file := range descendants {
+			if err := tx.Model(file).Update("path", file.Path).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// CreateTree 在一个事务中按顺序创建文件记录
+//
+// parents[i] 为 files[i] 的父文件夹在 files 中的下标，父项必须排在子项之前；
+// 为负数时保留 files[i] 自身的 ParentID，用于复制的根项目
+func (r *folderRepository) CreateTree(ctx context.Context, files []*models.File, parents []int) error {
+	if len(files) == 0 || len(files) != len(parents) {
+		return fmt.Errorf("文件和父项下标数量不一致")
+	}
+
+	return database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		for i, file := range files {
+			if parent := parents[i]; parent >= 0 {
+				if parent >= i {
+					return fmt.Errorf("父项必须排在子项之前")
+				}
+				parentID := files[parent].ID
+				file.ParentID = &parentID
+			}
+			if err := tx.Omit(clause.Associations).Create(file).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ListContents 分页查询文件夹下的可用子项，parentID为nil表示根目录
+//
+// 文件夹排在文件之前，同类按排序列排序，排序列相同时按ID排序保证分页稳定；
+// 按名称排序时使用 (user_id, parent_id, is_folder, name) 索引
+func (r *folderRepository) ListContents(ctx context.Context, userID uint, parentID *uint, filter ContentsFilter, order ContentsOrder, offset, limit int) ([]*models.File, int64, error) {
+	query := r.contentsQuery(ctx, userID, parentID, filter)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	column := ContentsOrderName
+	switch order.Column {
+	case ContentsOrderSize, ContentsOrderCreatedAt, ContentsOrderUpdatedAt:
+		column = order.Column
+	}
+	direction := "ASC"
+	if order.Desc {
+		direction = "DESC"
+	}
+
+	var files []*models.File
+	err := query.Order("is_folder DESC, " + column + " " + direction + ", id " + direction).
+		Offset(offset).
+		Limit(limit).
+		Find(&files).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return files, total, nil
+}
+
+// ListContentNames 查询文件夹下可用子项的ID、名称和类型，最多返回limit条
+//
+// 用于在内存中按自然排序或语言规则排序，调用方通过返回条数是否达到limit判断子项是否过多
+func (r *folderRepository) ListContentNames(ctx context.Context, userID uint, parentID *uint, filter ContentsFilter, limit int) ([]*models.File, error) {
+	var files []*models.File
+	err := r.contentsQuery(ctx, userID, parentID, filter).
+		Select("id", "name", "is_folder").
+		Order("id ASC").
+		Limit(limit).
+		Find(&files).Error
+	return files, err
+}
+
+// FindContents 按ID查询用户的可用文件，includeUploading为true时包含上传占位记录，结果顺序不确定
+func (r *folderRepository) FindContents(ctx context.Context, userID uint, ids []uint, includeUploading bool) ([]*models.File, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	var files []*models.File
+	err := database.Conn(ctx, r.db).
+		Where("user_id = ? AND id IN ?", userID, ids).
+		Where(contentsStatus(includeUploading)).
+		Find(&files).Error
+	return files, err
+}
+
+// contentsStatus 文件夹内容的状态条件，includeUploading为true时包含进行中的分片上传
+func contentsStatus(includeUploading bool) clause.Expression {
+	if includeUploading {
+		return gorm.Expr("(status = ? OR (status = ? AND upload_status = ?))", "active", "uploading", "uploading")
+	}
+	return gorm.Expr("status = ?", "active")
+}
+
+// folderDepthExpr 文件夹的嵌套层级：根目录下为1，此后每级路径加1
+const folderDepthExpr = "CASE WHEN path IN ('', '/') THEN 1 ELSE LENGTH(path) - LENGTH(REPLACE(path, '/', '')) + 1 END"
+
+// CountChildren 统计文件夹的直接子项数，parentID为nil表示根目录
+//
+// 与同名检查一致，统计所有未删除的子项，包括上传中的文件
+func (r *folderRepository) CountChildren(ctx context.Context, userID uint, parentID *uint) (int64, error) {
+	query := database.Conn(ctx, r.db).Model(&models.File{}).Where("user_id = ?", userID)
+	if parentID == nil {
+		query = query.Where("parent_id IS NULL")
+	} else {
+		query = query.Where("parent_id = ?", *parentID)
+	}
+
+	var count int64
+	err := query.Count(&count).Error
+	return count, err
+}
+
+// ListCrowdedFolders 查询直接子项数不少于minChildren的文件夹(含根目录)，按子项数降序
+func (r *folderRepository) ListCrowdedFolders(ctx context.Context, minChildren int64, limit int) ([]FolderChildCount, error) {
+	var counts []FolderChildCount
+	err := database.Conn(ctx, r.db).Model(&models.File{}).
+		Select("user_id, parent_id, COUNT(*) AS children").
+		Group("user_id, parent_id").
+		Having("COUNT(*) >= ?", minChildren).
+		Order("children DESC").
+		Limit(limit).
+		Scan(&counts).Error
+	return counts, err
+}
+
+// ListDeepFolders 查询嵌套层级不低于minDepth的未删除文件夹，按层级降序
+func (r *folderRepository) ListDeepFolders(ctx context.Context, minDepth, limit int) ([]*models.File, error) {
+	var folders []*models.File
+	err := database.Conn(ctx, r.db).
+		Where("is_folder = ? AND status = ?", true, "active").
+		Where(folderDepthExpr+" >= ?", minDepth).
+		Order(folderDepthExpr + " DESC").
+		Order("id").
+		Limit(limit).
+		Find(&folders).Error
+	return folders, err
+}
+
+// contentsQuery 文件夹下可用子项的查询条件
+func (r *folderRepository) contentsQuery(ctx context.Context, userID uint, parentID *uint, filter ContentsFilter) *gorm.DB {
+	query := database.Conn(ctx, r.db).Model(&models.File{}).
+		Where("user_id = ?", userID).
+		Where(contentsStatus(filter.IncludeUploading))
+	if !filter.IsZero() {
+		query = query.Where("is_folder = ?", false)
+	}
+	if filter.ScanStatus != "" {
+		query = query.Where("scan_status = ?", filter.ScanStatus)
+	}
+	if filter.PreviewStatus != "" {
+		status := filter.PreviewStatus
+		if status == models.PreviewStatusNone {
+			status = ""
+		}
+		query = query.Where("preview_status = ?", status)
+	}
+	query = tagsCondition(query, userID, filter)
+	if parentID == nil {
+		return query.Where("parent_id IS NULL")
+	}
+	return query.Where("parent_id = ?", *parentID)
+}
+
+// tagExists 文件带有用户标签的子查询条件，与 searchRepository 的标签筛选一致
+const tagExists = "EXISTS (SELECT 1 FROM file_tags WHERE file_tags.file_id = files.id AND file_tags.user_id = ? AND file_tags.tag IN ? AND file_tags.deleted_at IS NULL)"
+
+// tagsCondition 添加标签筛选条件，全部匹配时每个标签一个子查询
+func tagsCondition(query *gorm.DB, userID uint, filter ContentsFilter) *gorm.DB {
+	if len(filter.Tags) == 0 {
+		return query
+	}
+	if filter.MatchAnyTag {
+		return query.Where(tagExists, userID, filter.Tags)
+	}
+	for _, tag := range filter.Tags {
+		query = query.Where(tagExists, userID, []string{tag})
+	}
+	return query
+}
@@ -0,0 +1,39 @@
+package file
+
+import (
+	"context"
+	"time"
+
+	"cloudpan/internal/repository/models"
+)
+
+// FolderRuleRepository 文件夹自动化规则数据仓库接口
+//
+// 提供文件夹规则和执行日志的数据访问操作，包括：
+// 1. 规则管理：创建、查询、更新和删除规则
+// 2. 规则匹配：查询一组文件夹上启用的规则
+// 3. 执行记录：累加执行次数，写入和分页查询执行日志，清理过期日志
+//
+// 使用示例：
+//
+//	repo := NewFolderRuleRepository(db)
+//	rules, err := repo.ListActiveByFolders(ctx, userID, []uint{parentID, grandparentID})
+//	err = repo.CreateLog(ctx, log)
+type FolderRuleRepository interface {
+	// 规则管理
+	Create(ctx context.Context, rule *models.FolderRule) error
+	GetByID(ctx context.Context, id uint) (*models.FolderRule, error)
+	ListByUser(ctx context.Context, userID uint, folderID *uint) ([]*models.FolderRule, error)
+	CountByUser(ctx context.Context, userID uint) (int64, error)
+	Update(ctx context.Context, rule *models.FolderRule) error
+	Delete(ctx context.Context, id uint) error
+
+	// 规则匹配
+	ListActiveByFolders(ctx context.Context, userID uint, folderIDs []uint) ([]*models.FolderRule, error)
+
+	// 执行记录
+	RecordExecution(ctx context.Context, id uint, status string, at time.Time) error
+	CreateLog(ctx context.Context, log *models.FolderRuleLog) error
+	ListLogs(ctx context.Context, ruleID uint, limit, offset int) ([]*models.FolderRuleLog, int64, error)
+	DeleteLogsBefore(ctx context.Context, before time.Time, limit int) (int64, error)
+}
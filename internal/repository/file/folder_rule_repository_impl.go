@@ -0,0 +1,166 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/database"
+	"cloudpan/internal/repository/models"
+)
+
+// folderRuleRepository 文件夹自动化规则数据仓库实现
+type folderRuleRepository struct {
+	db *gorm.DB
+}
+
+// NewFolderRuleRepository 创建文件夹自动化规则数据仓库实例
+func NewFolderRuleRepository(db *gorm.DB) FolderRuleRepository {
+	return &folderRuleRepository{
+		db: db,
+	}
+}
+
+// Create 创建规则
+func (r *folderRuleRepository) Create(ctx context.Context, rule *models.FolderRule) error {
+	return database.Conn(ctx, r.db).Create(rule).Error
+}
+
+// GetByID 根据ID获取规则
+func (r *folderRuleRepository) GetByID(ctx context.Context, id uint) (*models.FolderRule, error) {
+	var rule models.FolderRule
+	if err := database.Conn(ctx, r.db).First(&rule, id).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// ListByUser 列出用户的规则，folderID 不为nil时只列出该文件夹上的规则
+func (r *folderRuleRepository) ListByUser(ctx context.Context, userID uint, folderID *uint) ([]*models.FolderRule, error) {
+	query := database.Conn(ctx, r.db).Where("user_id = ?", userID)
+	if folderID != nil {
+		query = query.Where("folder_id = ?", *folderID)
+	}
+
+	var rules []*models.FolderRule
+	if err := query.Order("id ASC").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// CountByUser 统计用户的规则数
+func (r *folderRuleRepository) CountByUser(ctx context.Context, userID uint) (int64, error) {
+	var count int64
+	err := database.Conn(ctx, r.db).Model(&models.FolderRule{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+// Update 保存规则的可编辑字段，不覆盖执行统计
+func (r *folderRuleRepository) Update(ctx context.Context, rule *models.FolderRule) error {
+	if rule.ID == 0 {
+		return fmt.Errorf("规则ID不能为空")
+	}
+
+	return database.Conn(ctx, r.db).Model(&models.FolderRule{}).
+		Where("id = ?", rule.ID).
+		Updates(map[string]interface{}{
+			"name":               rule.Name,
+			"condition":          rule.Condition,
+			"include_subfolders": rule.IncludeSubfolders,
+			"is_active":          rule.IsActive,
+			"action":             rule.Action,
+			"webhook_url":        rule.WebhookURL,
+			"webhook_secret":     rule.WebhookSecret,
+			"tags":               rule.Tags,
+			"target_folder_id":   rule.TargetFolderID,
+		}).Error
+}
+
+// Delete 删除规则
+func (r *folderRuleRepository) Delete(ctx context.Context, id uint) error {
+	return database.Conn(ctx, r.db).Delete(&models.FolderRule{}, id).Error
+}
+
+// ListActiveByFolders 查询一组文件夹上启用的规则，按创建顺序排列
+func (r *folderRuleRepository) ListActiveByFolders(ctx context.Context, userID uint, folderIDs []uint) ([]*models.FolderRule, error) {
+	if len(folderIDs) == 0 {
+		return nil, nil
+	}
+
+	var rules []*models.FolderRule
+	err := database.Conn(ctx, r.db).
+		Where("user_id = ? AND folder_id IN ? AND is_active = ?", userID, folderIDs, true).
+		Order("id ASC").
+		Find(&rules).Error
+	if err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// RecordExecution 累加执行次数并记录最后执行结果
+func (r *folderRuleRepository) RecordExecution(ctx context.Context, id uint, status string, at time.Time) error {
+	if id == 0 {
+		return fmt.Errorf("规则ID不能为空")
+	}
+
+	columns := map[string]interface{}{
+		"trigger_count":     gorm.Expr("trigger_count + ?", 1),
+		"last_status":       status,
+		"last_triggered_at": at,
+	}
+	if status == models.RuleLogStatusFailed {
+		columns["failure_count"] = gorm.Expr("failure_count + ?", 1)
+	}
+
+	// 使用UpdateColumns避免触发版本号自增，并发执行时由数据库原子累加
+	return database.Conn(ctx, r.db).Model(&models.FolderRule{}).
+		Where("id = ?", id).
+		UpdateColumns(columns).Error
+}
+
+// CreateLog 写入执行日志
+func (r *folderRuleRepository) CreateLog(ctx context.Context, log *models.FolderRuleLog) error {
+	return database.Conn(ctx, r.db).Create(log).Error
+}
+
+// ListLogs 分页查询规则的执行日志，按时间倒序
+func (r *folderRuleRepository) ListLogs(ctx context.Context, ruleID uint, limit, offset int) ([]*models.FolderRuleLog, int64, error) {
+	query := database.Conn(ctx, r.db).Model(&models.FolderRuleLog{}).Where("rule_id = ?", ruleID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var logs []*models.FolderRuleLog
+	err := query.Order("created_at DESC, id DESC").Limit(limit).Offset(offset).Find(&logs).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return logs, total, nil
+}
+
+// DeleteLogsBefore 分批删除早于指定时间的执行日志，返回删除的条数
+func (r *folderRuleRepository) DeleteLogsBefore(ctx context.Context, before time.Time, limit int) (int64, error) {
+	if limit <= 0 {
+		return 0, fmt.Errorf("删除数量必须大于0")
+	}
+
+	// MySQL不支持在子查询中引用被删除的表，先查出ID再删除
+	var ids []uint
+	err := database.Conn(ctx, r.db).Model(&models.FolderRuleLog{}).
+		Where("created_at < ?", before).
+		Order("id ASC").
+		Limit(limit).
+		Pluck("id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+
+	result := database.Conn(ctx, r.db).Where("id IN ?", ids).Delete(&models.FolderRuleLog{})
+	return result.RowsAffected, result.Error
+}
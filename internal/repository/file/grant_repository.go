@@ -0,0 +1,33 @@
+package file
+
+import (
+	"context"
+
+	"cloudpan/internal/repository/models"
+)
+
+// GrantRepository 文件访问授权数据仓库接口
+//
+// 提供文件和文件夹授权的数据访问操作，包括：
+// 1. 授权管理：按文件和授权对象写入或覆盖授权，删除授权
+// 2. 授权查询：列出文件上的全部授权
+// 3. 权限计算：查询一组文件(通常是文件及其上级文件夹)上对某用户或其所在团队生效的授权
+//
+// 使用示例：
+//
+//	repo := NewGrantRepository(db)
+//	err := repo.Upsert(ctx, &models.FileGrant{FileID: folderID, SubjectType: models.GrantSubjectUser, SubjectID: userID, Permissions: "read"})
+//	grants, err := repo.ListForSubjects(ctx, []uint{fileID, parentID}, userID, teamIDs)
+type GrantRepository interface {
+	// 授权管理
+	Upsert(ctx context.Context, grant *models.FileGrant) error
+	GetByID(ctx context.Context, id uint) (*models.FileGrant, error)
+	GetBySubject(ctx context.Context, fileID uint, subjectType string, subjectID uint) (*models.FileGrant, error)
+	Delete(ctx context.Context, id uint) error
+
+	// 授权查询
+	ListByFile(ctx context.Context, fileID uint) ([]*models.FileGrant, error)
+
+	// 权限计算
+	ListForSubjects(ctx context.Context, fileIDs []uint, userID uint, teamIDs []uint) ([]*models.FileGrant, error)
+}
@@ -0,0 +1,99 @@
+package file
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"cloudpan/internal/pkg/database"
+	"cloudpan/internal/repository/models"
+)
+
+// grantRepository 文件访问授权数据仓库实现
+type grantRepository struct {
+	db *gorm.DB
+}
+
+// NewGrantRepository 创建文件访问授权数据仓库实例
+func NewGrantRepository(db *gorm.DB) GrantRepository {
+	return &grantRepository{
+		db: db,
+	}
+}
+
+// Upsert 写入授权，同一文件和授权对象已有授权时覆盖权限和授权人
+func (r *grantRepository) Upsert(ctx context.Context, grant *models.FileGrant) error {
+	err := database.Conn(ctx, r.db).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "file_id"}, {Name: "subject_type"}, {Name: "subject_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"permissions", "granted_by", "updated_at"}),
+		}).
+		Create(grant).Error
+	if err != nil {
+		return fmt.Errorf("保存文件授权失败: %w", err)
+	}
+	return nil
+}
+
+// GetByID 根据ID获取授权，不存在时返回 gorm.ErrRecordNotFound
+func (r *grantRepository) GetByID(ctx context.Context, id uint) (*models.FileGrant, error) {
+	var grant models.FileGrant
+	if err := database.Conn(ctx, r.db).First(&grant, id).Error; err != nil {
+		return nil, err
+	}
+	return &grant, nil
+}
+
+// GetBySubject 获取文件上某个授权对象的授权，不存在时返回 gorm.ErrRecordNotFound
+func (r *grantRepository) GetBySubject(ctx context.Context, fileID uint, subjectType string, subjectID uint) (*models.FileGrant, error) {
+	var grant models.FileGrant
+	err := database.Conn(ctx, r.db).
+		Where("file_id = ? AND subject_type = ? AND subject_id = ?", fileID, subjectType, subjectID).
+		First(&grant).Error
+	if err != nil {
+		return nil, err
+	}
+	return &grant, nil
+}
+
+// Delete 删除授权
+func (r *grantRepository) Delete(ctx context.Context, id uint) error {
+	return database.Conn(ctx, r.db).Delete(&models.FileGrant{}, id).Error
+}
+
+// ListByFile 列出文件上的全部授权
+func (r *grantRepository) ListByFile(ctx context.Context, fileID uint) ([]*models.FileGrant, error) {
+	var grants []*models.FileGrant
+	err := database.Conn(ctx, r.db).
+		Where("file_id = ?", fileID).
+		Order("id ASC").
+		Find(&grants).Error
+	if err != nil {
+		return nil, err
+	}
+	return grants, nil
+}
+
+// ListForSubjects 查询一组文件上授予该用户或其所在团队的授权
+func (r *grantRepository) ListForSubjects(ctx context.Context, fileIDs []uint, userID uint, teamIDs []uint) ([]*models.FileGrant, error) {
+	if len(fileIDs) == 0 {
+		return []*models.FileGrant{}, nil
+	}
+
+	subjects := r.db.Where("subject_type = ? AND subject_id = ?", models.GrantSubjectUser, userID)
+	if len(teamIDs) > 0 {
+		subjects = subjects.Or("subject_type = ? AND subject_id IN ?", models.GrantSubjectTeam, teamIDs)
+	}
+
+	var grants []*models.FileGrant
+	err := database.Conn(ctx, r.db).
+		Where("file_id IN ?", fileIDs).
+		Where(subjects).
+		Find(&grants).Error
+	if err != nil {
+		return nil, err
+	}
+	return grants, nil
+}
@@ -0,0 +1,34 @@
+package file
+
+import (
+	"context"
+	"time"
+
+	"cloudpan/internal/repository/models"
+)
+
+// ModerationRepository 分享图片内容审核结果数据仓库接口
+//
+// 提供内容审核结果和管理员复核队列的数据访问操作，包括：
+// 1. 保存：按文件写入审核结果，同一文件重新审核时覆盖原结果
+// 2. 查询：按ID或文件获取结果，按审核结论和复核状态分页列出结果
+// 3. 复核：记录管理员的复核结论
+//
+// 使用示例：
+//
+//	repo := NewModerationRepository(db)
+//	result, err := repo.GetByFileID(ctx, fileID)
+//	err = repo.Save(ctx, result)
+//	err = repo.Review(ctx, result.ID, models.ModerationOverrideApproved, adminID, nil, time.Now())
+type ModerationRepository interface {
+	// 保存
+	Save(ctx context.Context, result *models.ContentModerationResult) error
+
+	// 查询
+	GetByID(ctx context.Context, id uint) (*models.ContentModerationResult, error)
+	GetByFileID(ctx context.Context, fileID uint) (*models.ContentModerationResult, error)
+	List(ctx context.Context, statuses []string, unreviewedOnly bool, limit, offset int) ([]*models.ContentModerationResult, int64, error)
+
+	// 复核
+	Review(ctx context.Context, id uint, override string, reviewedBy uint, note *string, reviewedAt time.Time) error
+}
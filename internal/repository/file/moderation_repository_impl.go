@@ -0,0 +1,116 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"cloudpan/internal/pkg/database"
+	"cloudpan/internal/repository/models"
+)
+
+// moderationRepository 分享图片内容审核结果数据仓库实现
+type moderationRepository struct {
+	db *gorm.DB
+}
+
+// NewModerationRepository 创建内容审核结果数据仓库实例
+func NewModerationRepository(db *gorm.DB) ModerationRepository {
+	return &moderationRepository{
+		db: db,
+	}
+}
+
+// Save 按文件写入审核结果，文件已有结果时覆盖审核内容和复核信息
+func (r *moderationRepository) Save(ctx context.Context, result *models.ContentModerationResult) error {
+	if result == nil || result.FileID == 0 {
+		return fmt.Errorf("审核结果和文件ID不能为空")
+	}
+
+	return database.Conn(ctx, r.db).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "file_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"user_id", "file_name", "file_hash", "status", "label", "score", "labels", "scanned_at",
+			"override", "reviewed_by", "reviewed_at", "review_note", "updated_at",
+		}),
+	}).Create(result).Error
+}
+
+// GetByID 根据ID获取审核结果
+func (r *moderationRepository) GetByID(ctx context.Context, id uint) (*models.ContentModerationResult, error) {
+	if id == 0 {
+		return nil, fmt.Errorf("审核结果ID不能为空")
+	}
+
+	var result models.ContentModerationResult
+	if err := database.Conn(ctx, r.db).First(&result, id).Error; err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetByFileID 获取文件的审核结果
+func (r *moderationRepository) GetByFileID(ctx context.Context, fileID uint) (*models.ContentModerationResult, error) {
+	if fileID == 0 {
+		return nil, fmt.Errorf("文件ID不能为空")
+	}
+
+	var result models.ContentModerationResult
+	if err := database.Conn(ctx, r.db).Where("file_id = ?", fileID).First(&result).Error; err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// List 分页获取审核结果，按审核时间倒序；statuses为空时查询全部结论，unreviewedOnly为true时只查询未复核的结果
+func (r *moderationRepository) List(ctx context.Context, statuses []string, unreviewedOnly bool, limit, offset int) ([]*models.ContentModerationResult, int64, error) {
+	query := database.Conn(ctx, r.db).Model(&models.ContentModerationResult{})
+	if len(statuses) > 0 {
+		query = query.Where("status IN ?", statuses)
+	}
+	if unreviewedOnly {
+		query = query.Where("override IS NULL")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var results []*models.ContentModerationResult
+	err := query.Order("scanned_at DESC, id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&results).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return results, total, nil
+}
+
+// Review 记录管理员的复核结论
+func (r *moderationRepository) Review(ctx context.Context, id uint, override string, reviewedBy uint, note *string, reviewedAt time.Time) error {
+	if id == 0 {
+		return fmt.Errorf("审核结果ID不能为空")
+	}
+
+	result := database.Conn(ctx, r.db).Model(&models.ContentModerationResult{}).
+		Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{
+			"override":    override,
+			"reviewed_by": reviewedBy,
+			"reviewed_at": reviewedAt,
+			"review_note": note,
+			"updated_at":  reviewedAt,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"cloudpan/internal/repository/models"
+)
+
+// ErrRetainedObjectUnavailable 保留的存储对象已恢复或已过保留期
+var ErrRetainedObjectUnavailable = errors.New("保留的存储对象已恢复或已过保留期")
+
+// RetainedObjectRepository 删除后保留的存储对象数据仓库接口
+//
+// 提供文件记录彻底删除后保留存储对象的数据访问操作，包括：
+// 1. 登记：彻底删除回收站项目时登记保留的存储对象，按原文件ID去重
+// 2. 查询：按用户分页列出保留记录，查询已过保留期且未恢复的记录
+// 3. 恢复：事务内标记已恢复并重新创建文件记录
+// 4. 删除：删除存储对象已删除的保留记录
+//
+// 使用示例：
+//
+//	repo := NewRetainedObjectRepository(db)
+//	err := repo.CreateBatch(ctx, objects)
+//	expired, err := repo.ListExpired(ctx, time.Now(), 100)
+//	err = repo.Restore(ctx, object.ID, file, adminID, time.Now())
+type RetainedObjectRepository interface {
+	// 登记
+	CreateBatch(ctx context.Context, objects []*models.RetainedObject) error
+
+	// 查询
+	GetByID(ctx context.Context, id uint) (*models.RetainedObject, error)
+	List(ctx context.Context, userID uint, limit, offset int) ([]*models.RetainedObject, int64, error)
+	ListExpired(ctx context.Context, now time.Time, limit int) ([]*models.RetainedObject, error)
+
+	// 恢复
+	Restore(ctx context.Context, id uint, file *models.File, restoredBy uint, restoredAt time.Time) error
+
+	// 删除
+	DeleteExpired(ctx context.Context, ids []uint) (int64, error)
+}
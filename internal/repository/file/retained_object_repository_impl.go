@@ -0,0 +1,134 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"cloudpan/internal/pkg/database"
+	"cloudpan/internal/repository/models"
+)
+
+// retainedObjectRepository 删除后保留的存储对象数据仓库实现
+type retainedObjectRepository struct {
+	db *gorm.DB
+}
+
+// NewRetainedObjectRepository 创建删除后保留的存储对象数据仓库实例
+func NewRetainedObjectRepository(db *gorm.DB) RetainedObjectRepository {
+	return &retainedObjectRepository{
+		db: db,
+	}
+}
+
+// CreateBatch 登记保留的存储对象
+//
+// 彻底删除失败重试时同一文件会再次登记，原文件ID冲突时保留已有记录
+func (r *retainedObjectRepository) CreateBatch(ctx context.Context, objects []*models.RetainedObject) error {
+	if len(objects) == 0 {
+		return nil
+	}
+	return database.Conn(ctx, r.db).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "file_id"}}, DoNothing: true}).
+		Create(&objects).Error
+}
+
+// GetByID 根据ID获取保留记录
+func (r *retainedObjectRepository) GetByID(ctx context.Context, id uint) (*models.RetainedObject, error) {
+	if id == 0 {
+		return nil, fmt.Errorf("保留记录ID不能为空")
+	}
+
+	var object models.RetainedObject
+	if err := database.Conn(ctx, r.db).First(&object, id).Error; err != nil {
+		return nil, err
+	}
+	return &object, nil
+}
+
+// List 分页获取保留记录，按删除时间倒序；userID为0时查询全部用户
+func (r *retainedObjectRepository) List(ctx context.Context, userID uint, limit, offset int) ([]*models.RetainedObject, int64, error) {
+	query := database.Conn(ctx, r.db).Model(&models.RetainedObject{})
+	if userID != 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var objects []*models.RetainedObject
+	err := query.Order("purged_at DESC, id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&objects).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return objects, total, nil
+}
+
+// ListExpired 获取已过保留期且未恢复的保留记录
+func (r *retainedObjectRepository) ListExpired(ctx context.Context, now time.Time, limit int) ([]*models.RetainedObject, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("查询数量必须大于0")
+	}
+
+	var objects []*models.RetainedObject
+	err := database.Conn(ctx, r.db).
+		Where("restored_at IS NULL AND delete_after <= ?", now).
+		Order("delete_after ASC").
+		Limit(limit).
+		Find(&objects).Error
+	if err != nil {
+		return nil, err
+	}
+	return objects, nil
+}
+
+// Restore 在一个事务中标记保留记录已恢复并创建文件记录
+//
+// 只有未恢复且仍在保留期内的记录可以恢复，否则返回 ErrRetainedObjectUnavailable；
+// 条件更新保证同一存储对象只恢复一次，不会被到期清理删除
+func (r *retainedObjectRepository) Restore(ctx context.Context, id uint, file *models.File, restoredBy uint, restoredAt time.Time) error {
+	if id == 0 || file == nil {
+		return fmt.Errorf("保留记录ID和文件不能为空")
+	}
+
+	return database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.RetainedObject{}).
+			Where("id = ? AND restored_at IS NULL AND delete_after > ?", id, restoredAt).
+			UpdateColumns(map[string]interface{}{
+				"restored_by": restoredBy,
+				"restored_at": restoredAt,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrRetainedObjectUnavailable
+		}
+
+		if err := tx.Create(file).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.RetainedObject{}).
+			Where("id = ?", id).
+			UpdateColumn("restored_file_id", file.ID).Error
+	})
+}
+
+// DeleteExpired 删除未恢复的保留记录，返回删除的记录数
+func (r *retainedObjectRepository) DeleteExpired(ctx context.Context, ids []uint) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	result := database.Conn(ctx, r.db).
+		Where("id IN ? AND restored_at IS NULL", ids).
+		Delete(&models.RetainedObject{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,48 @@
+package file
+
+import (
+	"context"
+	"time"
+
+	"cloudpan/internal/repository/models"
+)
+
+// SearchRepository 文件搜索数据仓库接口
+//
+// MySQL使用文件名、标签和描述上的FULLTEXT索引(ngram分词，支持中文)按相关度排序，
+// 其他数据库退化为LIKE匹配并按修改时间排序
+//
+// 使用示例：
+//
+//	repo := NewSearchRepository(db)
+//	files, total, err := repo.Search(ctx, SearchQuery{UserID: userID, Keyword: "报告", Extensions: []string{"pdf"}, Limit: 20})
+//	files, err = repo.FindActive(ctx, userID, ids)
+type SearchRepository interface {
+	Search(ctx context.Context, query SearchQuery) ([]*models.File, int64, error)
+	FindActive(ctx context.Context, userID uint, ids []uint) ([]*models.File, error)
+}
+
+// 搜索日期范围作用的字段
+const (
+	SearchDateCreated = "created" // 创建时间
+	SearchDateUpdated = "updated" // 修改时间
+)
+
+// SearchQuery 文件搜索条件，为空的条件不参与筛选
+type SearchQuery struct {
+	UserID     uint     // 文件所属用户
+	Keyword    string   // 关键词，匹配文件名、标签和描述
+	Tags       []string // 标签，文件需包含全部标签
+	Extensions []string // 扩展名(不含点，小写)，匹配任一
+	MimeTypes  []string // MIME类型，以/结尾时按前缀匹配(如image/)，匹配任一
+
+	MinSize *int64 // 最小文件大小(字节，含)
+	MaxSize *int64 // 最大文件大小(字节，含)
+
+	DateField string     // 日期范围作用的字段(SearchDateCreated等)，为空按修改时间
+	From      *time.Time // 开始时间(含)
+	To        *time.Time // 结束时间(不含)
+
+	Offset int
+	Limit  int
+}
@@ -0,0 +1,130 @@
+package file
+
+import (
+	"context"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"cloudpan/internal/pkg/database"
+	"cloudpan/internal/repository/models"
+)
+
+// searchFulltextColumns FULLTEXT索引覆盖的列，与 migrations/020 中的索引一致
+const searchFulltextColumns = "name, tags, description"
+
+// searchRepository 文件搜索数据仓库实现
+type searchRepository struct {
+	db *gorm.DB
+}
+
+// NewSearchRepository 创建文件搜索数据仓库实例
+func NewSearchRepository(db *gorm.DB) SearchRepository {
+	return &searchRepository{
+		db: db,
+	}
+}
+
+// Search 搜索用户的可用文件和文件夹，返回当前页和总数
+func (r *searchRepository) Search(ctx context.Context, query SearchQuery) ([]*models.File, int64, error) {
+	tx := database.Conn(ctx, r.db).Model(&models.File{}).
+		Where("user_id = ? AND status = ?", query.UserID, "active")
+
+	fulltext := ""
+	if keyword := strings.TrimSpace(query.Keyword); keyword != "" {
+		if r.db.Dialector.Name() == "mysql" {
+			fulltext = fulltextBooleanQuery(keyword)
+		}
+		if fulltext != "" {
+			tx = tx.Where("MATCH("+searchFulltextColumns+") AGAINST (? IN BOOLEAN MODE)", fulltext)
+		} else {
+			pattern := "%" + escapeLike(keyword) + "%"
+			tx = tx.Where("(name LIKE ? ESCAPE '!' OR tags LIKE ? ESCAPE '!' OR description LIKE ? ESCAPE '!')", pattern, pattern, pattern)
+		}
+	}
+	for _, tag := range query.Tags {
+		tx = tx.Where("EXISTS (SELECT 1 FROM file_tags WHERE file_tags.file_id = files.id AND file_tags.user_id = ? AND file_tags.tag = ? AND file_tags.deleted_at IS NULL)",
+			query.UserID, tag)
+	}
+	if len(query.Extensions) > 0 {
+		tx = tx.Where("LOWER(extension) IN ?", query.Extensions)
+	}
+	if len(query.MimeTypes) > 0 {
+		conditions := make([]string, 0, len(query.MimeTypes))
+		args := make([]interface{}, 0, len(query.MimeTypes))
+		for _, mimeType := range query.MimeTypes {
+			if strings.HasSuffix(mimeType, "/") {
+				conditions = append(conditions, "mime_type LIKE ? ESCAPE '!'")
+				args = append(args, escapeLike(mimeType)+"%")
+				continue
+			}
+			conditions = append(conditions, "mime_type = ?")
+			args = append(args, mimeType)
+		}
+		tx = tx.Where("("+strings.Join(conditions, " OR ")+")", args...)
+	}
+	if query.MinSize != nil {
+		tx = tx.Where("size >= ?", *query.MinSize)
+	}
+	if query.MaxSize != nil {
+		tx = tx.Where("size <= ?", *query.MaxSize)
+	}
+	dateColumn := "updated_at"
+	if query.DateField == SearchDateCreated {
+		dateColumn = "created_at"
+	}
+	if query.From != nil {
+		tx = tx.Where(dateColumn+" >= ?", *query.From)
+	}
+	if query.To != nil {
+		tx = tx.Where(dateColumn+" < ?", *query.To)
+	}
+
+	var total int64
+	if err := tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if fulltext != "" {
+		tx = tx.Order(clause.Expr{SQL: "MATCH(" + searchFulltextColumns + ") AGAINST (? IN BOOLEAN MODE) DESC", Vars: []interface{}{fulltext}})
+	}
+	var files []*models.File
+	err := tx.Order("updated_at DESC, id DESC").
+		Offset(query.Offset).
+		Limit(query.Limit).
+		Find(&files).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return files, total, nil
+}
+
+// FindActive 按ID查询用户的可用文件，结果顺序不确定
+func (r *searchRepository) FindActive(ctx context.Context, userID uint, ids []uint) ([]*models.File, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	var files []*models.File
+	err := database.Conn(ctx, r.db).
+		Where("user_id = ? AND status = ? AND id IN ?", userID, "active", ids).
+		Find(&files).Error
+	return files, err
+}
+
+// fulltextBooleanQuery 将关键词转换为BOOLEAN MODE查询，每个词都必须出现
+//
+// 去掉全文检索运算符，避免用户输入改变查询语义；没有可用词时返回空字符串
+func fulltextBooleanQuery(keyword string) string {
+	terms := strings.FieldsFunc(keyword, func(r rune) bool {
+		switch r {
+		case '+', '-', '<', '>', '(', ')', '~', '*', '"', '@', '\'', ' ', '\t', '\n', '\r':
+			return true
+		}
+		return false
+	})
+	for i, term := range terms {
+		terms[i] = `+"` + term + `"`
+	}
+	return strings.Join(terms, " ")
+}
@@ -0,0 +1,60 @@
+package file
+
+import (
+	"context"
+	"time"
+
+	basemodels "cloudpan/internal/pkg/database/models"
+	"cloudpan/internal/repository/models"
+)
+
+// ShareRepository 文件分享数据仓库接口
+//
+// 提供分享的查询和分享密码保护相关的数据访问操作，包括：
+// 1. 分享查询和创建：按ID、分享码查询，创建分享(分享码由模型钩子保证唯一)
+// 2. 密码管理：保存密码(由模型钩子哈希)
+// 3. 尝试保护：累计连续错误次数、临时锁定和解除
+// 4. 流量统计：按周期累计下载流量
+// 5. 分享设置：保存分享级的下载选项(如去除图片位置信息)
+// 6. 访问统计：累计访问次数和下载次数
+// 7. 过期处理：批量将已过期的分享标记为过期状态
+// 8. 状态管理：撤销分享时更新分享状态，举报暂停和审核时按当前状态条件更新，内容审核禁止时查询文件的有效分享
+//
+// 使用示例：
+//
+//	repo := NewShareRepository(db)
+//	share, err := repo.GetByCode(ctx, code)
+//	attempts, err := repo.IncrementPasswordFailures(ctx, share.ID)
+type ShareRepository interface {
+	// 基础查询和创建
+	GetByID(ctx context.Context, id uint) (*models.FileShare, error)
+	GetByCode(ctx context.Context, code string) (*models.FileShare, error)
+	ListActiveByFile(ctx context.Context, fileID uint) ([]*models.FileShare, error)
+	Create(ctx context.Context, share *models.FileShare) error
+
+	// 密码管理
+	UpdatePassword(ctx context.Context, share *models.FileShare) error
+
+	// 密码尝试保护
+	IncrementPasswordFailures(ctx context.Context, id uint) (int, error)
+	LockPassword(ctx context.Context, id uint, until time.Time) error
+	ResetPasswordFailures(ctx context.Context, id uint) error
+
+	// 流量统计
+	ResetTransferPeriod(ctx context.Context, id uint, periodStart time.Time) error
+	AddTransferUsage(ctx context.Context, id uint, bytes int64) error
+
+	// 分享设置
+	UpdateSettings(ctx context.Context, id uint, settings *basemodels.JSONMap) error
+
+	// 访问统计
+	IncrementAccessCount(ctx context.Context, id uint, at time.Time) error
+	IncrementDownloadCount(ctx context.Context, id uint) error
+
+	// 过期处理
+	ExpireShares(ctx context.Context, now time.Time, limit int) (int64, error)
+
+	// 状态管理
+	UpdateStatus(ctx context.Context, id uint, status string) error
+	TransitionStatus(ctx context.Context, id uint, from []string, to string) (bool, error)
+}
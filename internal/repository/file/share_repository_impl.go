@@ -0,0 +1,261 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"cloudpan/internal/pkg/database"
+	basemodels "cloudpan/internal/pkg/database/models"
+	"cloudpan/internal/repository/models"
+)
+
+// shareRepository 文件分享数据仓库实现
+type shareRepository struct {
+	db *gorm.DB
+}
+
+// NewShareRepository 创建文件分享数据仓库实例
+func NewShareRepository(db *gorm.DB) ShareRepository {
+	return &shareRepository{
+		db: db,
+	}
+}
+
+// GetByID 根据ID获取分享
+func (r *shareRepository) GetByID(ctx context.Context, id uint) (*models.FileShare, error) {
+	if id == 0 {
+		return nil, fmt.Errorf("分享ID不能为空")
+	}
+
+	var share models.FileShare
+	err := database.Conn(ctx, r.db).First(&share, id).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &share, nil
+}
+
+// GetByCode 根据分享码获取分享
+func (r *shareRepository) GetByCode(ctx context.Context, code string) (*models.FileShare, error) {
+	if code == "" {
+		return nil, fmt.Errorf("分享码不能为空")
+	}
+
+	var share models.FileShare
+	err := database.Conn(ctx, r.db).
+		Where("share_code = ?", code).
+		First(&share).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &share, nil
+}
+
+// Create 创建分享，明文密码由模型钩子哈希
+func (r *shareRepository) Create(ctx context.Context, share *models.FileShare) error {
+	if share == nil {
+		return fmt.Errorf("分享不能为空")
+	}
+	return database.Conn(ctx, r.db).Omit(clause.Associations).Create(share).Error
+}
+
+// UpdatePassword 保存分享密码，明文密码由模型钩子哈希
+func (r *shareRepository) UpdatePassword(ctx context.Context, share *models.FileShare) error {
+	if share == nil || share.ID == 0 {
+		return fmt.Errorf("分享ID不能为空")
+	}
+
+	return database.Conn(ctx, r.db).Model(share).
+		Select("password", "has_password", "password_failed_attempts", "password_locked_until").
+		Updates(share).Error
+}
+
+// IncrementPasswordFailures 原子地累加连续密码错误次数并返回累加后的值
+func (r *shareRepository) IncrementPasswordFailures(ctx context.Context, id uint) (int, error) {
+	if id == 0 {
+		return 0, fmt.Errorf("分享ID不能为空")
+	}
+
+	var attempts int
+	err := database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		// 使用UpdateColumn避免触发版本号自增
+		err := tx.Model(&models.FileShare{}).
+			Where("id = ?", id).
+			UpdateColumn("password_failed_attempts", gorm.Expr("password_failed_attempts + 1")).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&models.FileShare{}).
+			Where("id = ?", id).
+			Pluck("password_failed_attempts", &attempts).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return attempts, nil
+}
+
+// LockPassword 临时锁定分享的密码验证并清零错误次数
+func (r *shareRepository) LockPassword(ctx context.Context, id uint, until time.Time) error {
+	if id == 0 {
+		return fmt.Errorf("分享ID不能为空")
+	}
+
+	return database.Conn(ctx, r.db).Model(&models.FileShare{}).
+		Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{
+			"password_failed_attempts": 0,
+			"password_locked_until":    until,
+		}).Error
+}
+
+// ResetPasswordFailures 验证成功后清零错误次数并解除锁定
+func (r *shareRepository) ResetPasswordFailures(ctx context.Context, id uint) error {
+	if id == 0 {
+		return fmt.Errorf("分享ID不能为空")
+	}
+
+	return database.Conn(ctx, r.db).Model(&models.FileShare{}).
+		Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{
+			"password_failed_attempts": 0,
+			"password_locked_until":    nil,
+		}).Error
+}
+
+// ResetTransferPeriod 进入新的统计周期时清零已用流量
+//
+// 仅当记录的周期早于 periodStart 时更新，多个实例并发重置不会清掉新周期内已累计的流量
+func (r *shareRepository) ResetTransferPeriod(ctx context.Context, id uint, periodStart time.Time) error {
+	if id == 0 {
+		return fmt.Errorf("分享ID不能为空")
+	}
+
+	return database.Conn(ctx, r.db).Model(&models.FileShare{}).
+		Where("id = ?", id).
+		Where("transfer_period_start IS NULL OR transfer_period_start < ?", periodStart).
+		UpdateColumns(map[string]interface{}{
+			"transfer_used":         0,
+			"transfer_period_start": periodStart,
+		}).Error
+}
+
+// AddTransferUsage 原子地累加当前周期已消耗的下载流量
+func (r *shareRepository) AddTransferUsage(ctx context.Context, id uint, bytes int64) error {
+	if id == 0 {
+		return fmt.Errorf("分享ID不能为空")
+	}
+
+	return database.Conn(ctx, r.db).Model(&models.FileShare{}).
+		Where("id = ?", id).
+		UpdateColumn("transfer_used", gorm.Expr("transfer_used + ?", bytes)).Error
+}
+
+// UpdateSettings 保存分享设置
+func (r *shareRepository) UpdateSettings(ctx context.Context, id uint, settings *basemodels.JSONMap) error {
+	if id == 0 {
+		return fmt.Errorf("分享ID不能为空")
+	}
+
+	return database.Conn(ctx, r.db).Model(&models.FileShare{}).
+		Where("id = ?", id).
+		UpdateColumn("settings", settings).Error
+}
+
+// IncrementAccessCount 原子地累加访问次数并更新最后访问时间
+func (r *shareRepository) IncrementAccessCount(ctx context.Context, id uint, at time.Time) error {
+	if id == 0 {
+		return fmt.Errorf("分享ID不能为空")
+	}
+
+	return database.Conn(ctx, r.db).Model(&models.FileShare{}).
+		Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{
+			"access_count":     gorm.Expr("access_count + 1"),
+			"last_accessed_at": at,
+		}).Error
+}
+
+// IncrementDownloadCount 原子地累加下载次数
+func (r *shareRepository) IncrementDownloadCount(ctx context.Context, id uint) error {
+	if id == 0 {
+		return fmt.Errorf("分享ID不能为空")
+	}
+
+	return database.Conn(ctx, r.db).Model(&models.FileShare{}).
+		Where("id = ?", id).
+		UpdateColumn("download_count", gorm.Expr("download_count + 1")).Error
+}
+
+// ExpireShares 将一批已过期但仍为有效状态的分享标记为过期，返回更新的记录数
+//
+// 每次最多更新 limit 条，调用方循环调用直到返回值小于 limit
+func (r *shareRepository) ExpireShares(ctx context.Context, now time.Time, limit int) (int64, error) {
+	if limit <= 0 {
+		return 0, fmt.Errorf("批量大小必须大于0")
+	}
+
+	var ids []uint
+	err := database.Conn(ctx, r.db).Model(&models.FileShare{}).
+		Where("status = ? AND expires_at IS NOT NULL AND expires_at < ?", "active", now).
+		Limit(limit).
+		Pluck("id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+
+	// 使用UpdateColumn避免触发版本号自增；再次限定状态，避免覆盖并发修改
+	result := database.Conn(ctx, r.db).Model(&models.FileShare{}).
+		Where("id IN ? AND status = ?", ids, "active").
+		UpdateColumn("status", "expired")
+	return result.RowsAffected, result.Error
+}
+
+// UpdateStatus 更新分享状态
+func (r *shareRepository) UpdateStatus(ctx context.Context, id uint, status string) error {
+	if id == 0 {
+		return fmt.Errorf("分享ID不能为空")
+	}
+
+	return database.Conn(ctx, r.db).Model(&models.FileShare{}).
+		Where("id = ?", id).
+		UpdateColumn("status", status).Error
+}
+
+// ListActiveByFile 获取文件有效(含举报暂停)的分享
+func (r *shareRepository) ListActiveByFile(ctx context.Context, fileID uint) ([]*models.FileShare, error) {
+	if fileID == 0 {
+		return nil, fmt.Errorf("文件ID不能为空")
+	}
+
+	var shares []*models.FileShare
+	err := database.Conn(ctx, r.db).
+		Where("file_id = ? AND status IN ?", fileID, []string{models.ShareStatusActive, models.ShareStatusSuspended}).
+		Order("id").
+		Find(&shares).Error
+	return shares, err
+}
+
+// TransitionStatus 分享当前状态为from之一时更新为to，返回是否更新
+//
+// 条件更新避免覆盖并发的撤销、过期等状态变化
+func (r *shareRepository) TransitionStatus(ctx context.Context, id uint, from []string, to string) (bool, error) {
+	if id == 0 {
+		return false, fmt.Errorf("分享ID不能为空")
+	}
+	if len(from) == 0 {
+		return false, nil
+	}
+
+	result := database.Conn(ctx, r.db).Model(&models.FileShare{}).
+		Where("id = ? AND status IN ?", id, from).
+		UpdateColumn("status", to)
+	return result.RowsAffected > 0, result.Error
+}
@@ -0,0 +1,35 @@
+package file
+
+import (
+	"context"
+
+	"cloudpan/internal/repository/models"
+)
+
+// TagCount 标签及使用该标签的可用文件数
+type TagCount struct {
+	Tag   string `json:"tag"`   // 标签名称
+	Count int64  `json:"count"` // 使用该标签的可用文件和文件夹数
+}
+
+// TagRepository 文件标签数据仓库接口
+//
+// 提供文件标签的数据访问操作，标签唯一性由服务层检查：
+// 1. 文件标签：按文件查询、添加和删除用户的标签
+// 2. 用户标签：统计用户每个标签下的可用文件数
+//
+// 使用示例：
+//
+//	repo := NewTagRepository(db)
+//	tags, err := repo.ListByFile(ctx, fileID, userID)
+//	err = repo.Create(ctx, &models.FileTag{FileID: fileID, UserID: userID, Tag: "合同"})
+//	counts, err := repo.CountByUser(ctx, userID)
+type TagRepository interface {
+	// 文件标签
+	ListByFile(ctx context.Context, fileID, userID uint) ([]*models.FileTag, error)
+	Create(ctx context.Context, tag *models.FileTag) error
+	Delete(ctx context.Context, id uint) error
+
+	// 用户标签
+	CountByUser(ctx context.Context, userID uint) ([]TagCount, error)
+}
@@ -0,0 +1,62 @@
+package file
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/database"
+	"cloudpan/internal/repository/models"
+)
+
+// tagRepository 文件标签数据仓库实现
+type tagRepository struct {
+	db *gorm.DB
+}
+
+// NewTagRepository 创建文件标签数据仓库实例
+func NewTagRepository(db *gorm.DB) TagRepository {
+	return &tagRepository{
+		db: db,
+	}
+}
+
+// ListByFile 获取用户在文件上的标签，按添加顺序排列
+func (r *tagRepository) ListByFile(ctx context.Context, fileID, userID uint) ([]*models.FileTag, error) {
+	var tags []*models.FileTag
+	err := database.Conn(ctx, r.db).
+		Where("file_id = ? AND user_id = ?", fileID, userID).
+		Order("id").
+		Find(&tags).Error
+	return tags, err
+}
+
+// Create 添加文件标签
+func (r *tagRepository) Create(ctx context.Context, tag *models.FileTag) error {
+	if tag == nil {
+		return fmt.Errorf("标签不能为空")
+	}
+	return database.Conn(ctx, r.db).Create(tag).Error
+}
+
+// Delete 删除文件标签
+func (r *tagRepository) Delete(ctx context.Context, id uint) error {
+	if id == 0 {
+		return fmt.Errorf("标签ID不能为空")
+	}
+	return database.Conn(ctx, r.db).Delete(&models.FileTag{}, id).Error
+}
+
+// CountByUser 统计用户每个标签下的可用文件数，按文件数降序、标签名升序排列
+func (r *tagRepository) CountByUser(ctx context.Context, userID uint) ([]TagCount, error) {
+	var counts []TagCount
+	err := database.Conn(ctx, r.db).Model(&models.FileTag{}).
+		Select("file_tags.tag AS tag, COUNT(*) AS count").
+		Joins("JOIN files ON files.id = file_tags.file_id AND files.status = ? AND files.deleted_at IS NULL", "active").
+		Where("file_tags.user_id = ?", userID).
+		Group("file_tags.tag").
+		Order("count DESC, tag").
+		Scan(&counts).Error
+	return counts, err
+}
@@ -0,0 +1,47 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"cloudpan/internal/repository/models"
+)
+
+// ErrTransferStateChanged 完成转移时转移已不是已接受状态，或文件已不属于原所有者
+var ErrTransferStateChanged = errors.New("转移状态或文件所有者已变更")
+
+// 转移记录查询方向
+const (
+	TransferDirectionIncoming = "incoming" // 转给用户的
+	TransferDirectionOutgoing = "outgoing" // 用户发起的
+)
+
+// TransferRepository 文件所有权转移数据仓库接口
+//
+// 提供文件所有权转移的数据访问操作，包括：
+// 1. 转移记录：创建转移，按用户和方向分页查询，查询涉及一组文件的进行中转移
+// 2. 状态变更：按原状态条件更新为接受、拒绝或取消，记录完成失败的原因，分批把过期未接受的转移标记为过期
+// 3. 完成转移：在一个事务中把文件及全部子项改到接收方名下，处理分享和授权，并把转移标记为已完成
+//
+// 使用示例：
+//
+//	repo := NewTransferRepository(db)
+//	err := repo.Create(ctx, &models.FileTransfer{FileID: folderID, FromUserID: 7, ToUserID: 8, Status: models.TransferStatusPending})
+//	ok, err := repo.Respond(ctx, transferID, []string{models.TransferStatusPending}, models.TransferStatusAccepted, time.Now())
+//	err = repo.Complete(ctx, transfer, root, descendants)
+type TransferRepository interface {
+	// 转移记录
+	Create(ctx context.Context, transfer *models.FileTransfer) error
+	GetByID(ctx context.Context, id uint) (*models.FileTransfer, error)
+	ListByUser(ctx context.Context, userID uint, direction, status string, limit, offset int) ([]*models.FileTransfer, int64, error)
+	ListOpenByFiles(ctx context.Context, fileIDs []uint) ([]*models.FileTransfer, error)
+
+	// 状态变更
+	Respond(ctx context.Context, id uint, from []string, to string, at time.Time) (bool, error)
+	RecordFailure(ctx context.Context, id uint, message string) error
+	ExpirePending(ctx context.Context, now time.Time, limit int) (int64, error)
+
+	// 完成转移
+	Complete(ctx context.Context, transfer *models.FileTransfer, root *models.File, descendants []*models.File) error
+}
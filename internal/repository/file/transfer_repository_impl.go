@@ -0,0 +1,215 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/database"
+	"cloudpan/internal/repository/models"
+)
+
+// transferBatchSize 完成转移时按文件ID批量更新分享和授权的批大小
+const transferBatchSize = 500
+
+// transferRepository 文件所有权转移数据仓库实现
+type transferRepository struct {
+	db *gorm.DB
+}
+
+// NewTransferRepository 创建文件所有权转移数据仓库实例
+func NewTransferRepository(db *gorm.DB) TransferRepository {
+	return &transferRepository{
+		db: db,
+	}
+}
+
+// Create 创建转移记录
+func (r *transferRepository) Create(ctx context.Context, transfer *models.FileTransfer) error {
+	if transfer == nil {
+		return fmt.Errorf("转移记录不能为空")
+	}
+	return database.Conn(ctx, r.db).Create(transfer).Error
+}
+
+// GetByID 根据ID获取转移记录，不存在时返回 gorm.ErrRecordNotFound
+func (r *transferRepository) GetByID(ctx context.Context, id uint) (*models.FileTransfer, error) {
+	var transfer models.FileTransfer
+	if err := database.Conn(ctx, r.db).First(&transfer, id).Error; err != nil {
+		return nil, err
+	}
+	return &transfer, nil
+}
+
+// ListByUser 按创建时间倒序分页查询用户的转移记录
+//
+// direction 为 TransferDirectionIncoming 或 TransferDirectionOutgoing 时只查询转给用户的或用户发起的，
+// 为空时两者都查询；status 为空时不按状态筛选
+func (r *transferRepository) ListByUser(ctx context.Context, userID uint, direction, status string, limit, offset int) ([]*models.FileTransfer, int64, error) {
+	query := database.Conn(ctx, r.db).Model(&models.FileTransfer{})
+	switch direction {
+	case TransferDirectionIncoming:
+		query = query.Where("to_user_id = ?", userID)
+	case TransferDirectionOutgoing:
+		query = query.Where("from_user_id = ?", userID)
+	default:
+		query = query.Where("from_user_id = ? OR to_user_id = ?", userID, userID)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var transfers []*models.FileTransfer
+	err := query.Order("created_at DESC, id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&transfers).Error
+	return transfers, total, err
+}
+
+// ListOpenByFiles 查询涉及一组文件的等待接受或已接受未完成的转移
+func (r *transferRepository) ListOpenByFiles(ctx context.Context, fileIDs []uint) ([]*models.FileTransfer, error) {
+	if len(fileIDs) == 0 {
+		return nil, nil
+	}
+	var transfers []*models.FileTransfer
+	err := database.Conn(ctx, r.db).
+		Where("file_id IN ? AND status IN ?", fileIDs, []string{models.TransferStatusPending, models.TransferStatusAccepted}).
+		Find(&transfers).Error
+	return transfers, err
+}
+
+// Respond 把转移从 from 中的状态改为 to 状态并记录时间，转移已不是 from 中的状态时返回false
+func (r *transferRepository) Respond(ctx context.Context, id uint, from []string, to string, at time.Time) (bool, error) {
+	if id == 0 {
+		return false, fmt.Errorf("转移ID不能为空")
+	}
+	if len(from) == 0 {
+		return false, nil
+	}
+	result := database.Conn(ctx, r.db).Model(&models.FileTransfer{}).
+		Where("id = ? AND status IN ?", id, from).
+		Updates(map[string]interface{}{
+			"status":       to,
+			"responded_at": at,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// RecordFailure 记录已接受的转移最近一次完成失败的原因
+func (r *transferRepository) RecordFailure(ctx context.Context, id uint, message string) error {
+	if id == 0 {
+		return fmt.Errorf("转移ID不能为空")
+	}
+	return database.Conn(ctx, r.db).Model(&models.FileTransfer{}).
+		Where("id = ? AND status = ?", id, models.TransferStatusAccepted).
+		UpdateColumn("error", message).Error
+}
+
+// ExpirePending 把超过截止时间仍未接受的转移标记为过期，每次最多处理 limit 条
+func (r *transferRepository) ExpirePending(ctx context.Context, now time.Time, limit int) (int64, error) {
+	if limit <= 0 {
+		return 0, fmt.Errorf("批量大小必须大于0")
+	}
+
+	var ids []uint
+	err := database.Conn(ctx, r.db).Model(&models.FileTransfer{}).
+		Where("status = ? AND expires_at IS NOT NULL AND expires_at < ?", models.TransferStatusPending, now).
+		Limit(limit).
+		Pluck("id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+
+	// 再次限定状态，避免覆盖同时发生的接受
+	result := database.Conn(ctx, r.db).Model(&models.FileTransfer{}).
+		Where("id IN ? AND status = ?", ids, models.TransferStatusPending).
+		UpdateColumn("status", models.TransferStatusExpired)
+	return result.RowsAffected, result.Error
+}
+
+// Complete 在一个事务中完成已接受的转移
+//
+// root 和 descendants 的所有者、位置和路径已由调用方改为接收方空间中的新值；根项目仍属于原所有者时才写入，
+// 逐条更新以触发更新钩子。子树上的分享按转移的 ShareMode 全部改由接收方分享，或停用其中有效的分享，
+// 授予接收方本人的授权因接收方成为所有者而删除。转移已不是已接受状态或根项目已不属于原所有者时
+// 返回 ErrTransferStateChanged 且不做任何修改
+func (r *transferRepository) Complete(ctx context.Context, transfer *models.FileTransfer, root *models.File, descendants []*models.File) error {
+	if transfer == nil || transfer.ID == 0 || root == nil || root.ID == 0 {
+		return fmt.Errorf("转移记录和文件不能为空")
+	}
+
+	return database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.FileTransfer{}).
+			Where("id = ? AND status = ?", transfer.ID, models.TransferStatusAccepted).
+			Updates(map[string]interface{}{
+				"status":       models.TransferStatusCompleted,
+				"file_name":    transfer.FileName,
+				"items":        transfer.Items,
+				"size":         transfer.Size,
+				"error":        nil,
+				"completed_at": transfer.CompletedAt,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrTransferStateChanged
+		}
+
+		result = tx.Model(root).
+			Where("user_id = ?", transfer.FromUserID).
+			Updates(map[string]interface{}{
+				"user_id":   root.UserID,
+				"name":      root.Name,
+				"extension": root.Extension,
+				"parent_id": root.ParentID,
+				"path":      root.Path,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrTransferStateChanged
+		}
+
+		ids := []uint{root.ID}
+		for _, file := range descendants {
+			err := tx.Model(file).Updates(map[string]interface{}{
+				"user_id": file.UserID,
+				"path":    file.Path,
+			}).Error
+			if err != nil {
+				return err
+			}
+			ids = append(ids, file.ID)
+		}
+
+		for start := 0; start < len(ids); start += transferBatchSize {
+			batch := ids[start:min(start+transferBatchSize, len(ids))]
+			shares := tx.Model(&models.FileShare{}).Where("file_id IN ?", batch)
+			var err error
+			if transfer.ShareMode == models.TransferShareRevoke {
+				err = shares.Where("status = ?", models.ShareStatusActive).UpdateColumn("status", models.ShareStatusDisabled).Error
+			} else {
+				err = shares.UpdateColumn("sharer_id", transfer.ToUserID).Error
+			}
+			if err != nil {
+				return err
+			}
+
+			err = tx.Where("file_id IN ? AND subject_type = ? AND subject_id = ?", batch, models.GrantSubjectUser, transfer.ToUserID).
+				Delete(&models.FileGrant{}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
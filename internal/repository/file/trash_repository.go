@@ -0,0 +1,46 @@
+package file
+
+import (
+	"context"
+	"time"
+
+	"cloudpan/internal/repository/models"
+)
+
+// TrashRepository 回收站数据仓库接口
+//
+// 提供回收站相关的数据访问操作，包括：
+// 1. 移入回收站：登记回收站项目并软删除文件及其子项
+// 2. 回收站查询：按用户分页列出未恢复的项目，统计用户回收站的项目数和占用空间，查询已过保留期的项目
+// 3. 子树查询：查询包括已删除记录在内的文件及其全部子项，检查同名文件
+// 4. 恢复：撤销软删除并写入恢复后的路径
+// 5. 彻底删除：物理删除文件记录和对应的回收站项目，返回释放的存储用量
+//
+// 使用示例：
+//
+//	repo := NewTrashRepository(db)
+//	err := repo.MoveToTrash(ctx, entry, fileIDs)
+//	items, total, err := repo.ListByUser(ctx, userID, 20, 0)
+//	reclaimed, err := repo.Purge(ctx, fileIDs)
+type TrashRepository interface {
+	// 移入回收站
+	MoveToTrash(ctx context.Context, entry *models.RecycleBin, fileIDs []uint) error
+
+	// 回收站查询
+	GetByID(ctx context.Context, id uint) (*models.RecycleBin, error)
+	ListByUser(ctx context.Context, userID uint, limit, offset int) ([]*models.RecycleBin, int64, error)
+	ListByUserBefore(ctx context.Context, userID uint, before time.Time, afterID uint, limit int) ([]*models.RecycleBin, error)
+	UsageByUser(ctx context.Context, userID uint) (int64, int64, error)
+	ListExpired(ctx context.Context, now time.Time, limit int) ([]*models.RecycleBin, error)
+
+	// 子树查询
+	GetFile(ctx context.Context, id uint) (*models.File, error)
+	ListSubtree(ctx context.Context, root *models.File) ([]*models.File, error)
+	NameExists(ctx context.Context, userID uint, parentID *uint, name string) (bool, error)
+
+	// 恢复
+	Restore(ctx context.Context, entry *models.RecycleBin, files []*models.File, restoredBy uint, restoredAt time.Time) error
+
+	// 彻底删除
+	Purge(ctx context.Context, fileIDs []uint) (int64, error)
+}
@@ -0,0 +1,293 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/database"
+	"cloudpan/internal/repository/models"
+)
+
+// trashRepository 回收站数据仓库实现
+type trashRepository struct {
+	db *gorm.DB
+}
+
+// NewTrashRepository 创建回收站数据仓库实例
+func NewTrashRepository(db *gorm.DB) TrashRepository {
+	return &trashRepository{
+		db: db,
+	}
+}
+
+// MoveToTrash 在一个事务中登记回收站项目并软删除文件
+//
+// 只有可用的文件被标记为deleted状态，上传中等其他状态保持不变，恢复时据此还原状态
+func (r *trashRepository) MoveToTrash(ctx context.Context, entry *models.RecycleBin, fileIDs []uint) error {
+	if entry == nil || len(fileIDs) == 0 {
+		return fmt.Errorf("回收站项目和文件ID不能为空")
+	}
+
+	return database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(entry).Error; err != nil {
+			return err
+		}
+
+		err := tx.Model(&models.File{}).
+			Where("id IN ? AND status = ?", fileIDs, "active").
+			UpdateColumn("status", "deleted").Error
+		if err != nil {
+			return err
+		}
+
+		// 逐条加载后删除，触发删除钩子以更新索引
+		var files []*models.File
+		if err := tx.Where("id IN ?", fileIDs).Find(&files).Error; err != nil {
+			return err
+		}
+		if len(files) == 0 {
+			return nil
+		}
+		return tx.Delete(&files).Error
+	})
+}
+
+// GetByID 根据ID获取回收站项目
+func (r *trashRepository) GetByID(ctx context.Context, id uint) (*models.RecycleBin, error) {
+	if id == 0 {
+		return nil, fmt.Errorf("回收站项目ID不能为空")
+	}
+
+	var entry models.RecycleBin
+	if err := database.Conn(ctx, r.db).First(&entry, id).Error; err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// ListByUser 分页获取用户未恢复的回收站项目，按移入时间倒序
+func (r *trashRepository) ListByUser(ctx context.Context, userID uint, limit, offset int) ([]*models.RecycleBin, int64, error) {
+	if userID == 0 {
+		return nil, 0, fmt.Errorf("用户ID不能为空")
+	}
+
+	query := database.Conn(ctx, r.db).Model(&models.RecycleBin{}).
+		Where("user_id = ? AND is_restored = ?", userID, false)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var entries []*models.RecycleBin
+	err := query.Order("trashed_at DESC, id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&entries).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return entries, total, nil
+}
+
+// ListByUserBefore 按ID顺序获取用户在指定时间之前移入且未恢复的回收站项目，afterID为上一批最后一项的ID
+func (r *trashRepository) ListByUserBefore(ctx context.Context, userID uint, before time.Time, afterID uint, limit int) ([]*models.RecycleBin, error) {
+	if userID == 0 {
+		return nil, fmt.Errorf("用户ID不能为空")
+	}
+	if limit <= 0 {
+		return nil, fmt.Errorf("查询数量必须大于0")
+	}
+
+	var entries []*models.RecycleBin
+	err := database.Conn(ctx, r.db).
+		Where("user_id = ? AND is_restored = ? AND trashed_at <= ? AND id > ?", userID, false, before, afterID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// UsageByUser 统计用户未恢复的回收站项目数和登记的存储用量之和
+func (r *trashRepository) UsageByUser(ctx context.Context, userID uint) (int64, int64, error) {
+	if userID == 0 {
+		return 0, 0, fmt.Errorf("用户ID不能为空")
+	}
+
+	var usage struct {
+		Items int64
+		Size  int64
+	}
+	err := database.Conn(ctx, r.db).Model(&models.RecycleBin{}).
+		Select("COUNT(*) AS items, COALESCE(SUM(file_size), 0) AS size").
+		Where("user_id = ? AND is_restored = ?", userID, false).
+		Scan(&usage).Error
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return usage.Items, usage.Size, nil
+}
+
+// ListExpired 获取已超过保留期且未恢复的回收站项目
+func (r *trashRepository) ListExpired(ctx context.Context, now time.Time, limit int) ([]*models.RecycleBin, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("查询数量必须大于0")
+	}
+
+	var entries []*models.RecycleBin
+	err := database.Conn(ctx, r.db).
+		Where("is_restored = ? AND auto_delete_at < ?", false, now).
+		Order("auto_delete_at ASC").
+		Limit(limit).
+		Find(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// GetFile 根据ID获取文件，包括已软删除的文件
+func (r *trashRepository) GetFile(ctx context.Context, id uint) (*models.File, error) {
+	if id == 0 {
+		return nil, fmt.Errorf("文件ID不能为空")
+	}
+
+	var file models.File
+	if err := database.Conn(ctx, r.db).Unscoped().First(&file, id).Error; err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+// ListSubtree 获取文件及其全部子项，包括已软删除的记录
+//
+// 子项按路径前缀匹配：文件夹 /a/b 的子项路径为 /a/b 或以 /a/b/ 开头
+func (r *trashRepository) ListSubtree(ctx context.Context, root *models.File) ([]*models.File, error) {
+	if root == nil || root.ID == 0 {
+		return nil, fmt.Errorf("文件不能为空")
+	}
+	if !root.IsFolder {
+		return []*models.File{root}, nil
+	}
+
+	fullPath := root.GetFullPath()
+	var files []*models.File
+	err := database.Conn(ctx, r.db).Unscoped().
+		Where("user_id = ?", root.UserID).
+		Where("id = ? OR path = ? OR path LIKE ? ESCAPE '!'", root.ID, fullPath, escapeLike(fullPath)+"/%").
+		Order("id ASC").
+		Find(&files).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// NameExists 检查目标文件夹下是否已有同名的未删除文件，parentID为nil表示根目录
+func (r *trashRepository) NameExists(ctx context.Context, userID uint, parentID *uint, name string) (bool, error) {
+	query := database.Conn(ctx, r.db).Model(&models.File{}).
+		Where("user_id = ? AND name = ?", userID, name)
+	if parentID == nil {
+		query = query.Where("parent_id IS NULL")
+	} else {
+		query = query.Where("parent_id = ?", *parentID)
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// Restore 在一个事务中撤销文件的软删除、写入恢复后的位置，并将回收站项目标记为已恢复
+//
+// files 中的名称、父文件夹和路径由调用方计算；deleted状态还原为active
+func (r *trashRepository) Restore(ctx context.Context, entry *models.RecycleBin, files []*models.File, restoredBy uint, restoredAt time.Time) error {
+	if entry == nil || len(files) == 0 {
+		return fmt.Errorf("回收站项目和文件不能为空")
+	}
+
+	return database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		var restorePath string
+		for _, file := range files {
+			updates := map[string]interface{}{
+				"deleted_at": nil,
+				"path":       file.Path,
+			}
+			if file.ID == entry.FileID {
+				updates["name"] = file.Name
+				updates["parent_id"] = file.ParentID
+				restorePath = file.GetFullPath()
+			}
+			if file.Status == "deleted" {
+				updates["status"] = "active"
+			}
+			if err := tx.Unscoped().Model(file).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Model(entry).Updates(map[string]interface{}{
+			"is_restored":  true,
+			"restored_by":  restoredBy,
+			"restored_at":  restoredAt,
+			"restore_path": restorePath,
+		}).Error
+	})
+}
+
+// Purge 物理删除文件记录及引用这些文件的回收站项目，返回未恢复项目登记的存储用量之和
+//
+// 调用方应先删除存储对象；重复调用时已删除的记录被忽略
+func (r *trashRepository) Purge(ctx context.Context, fileIDs []uint) (int64, error) {
+	if len(fileIDs) == 0 {
+		return 0, nil
+	}
+
+	var reclaimed int64
+	err := database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.RecycleBin{}).
+			Where("file_id IN ? AND is_restored = ?", fileIDs, false).
+			Select("COALESCE(SUM(file_size), 0)").
+			Scan(&reclaimed).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("file_id IN ?", fileIDs).Delete(&models.RecycleBin{}).Error; err != nil {
+			return err
+		}
+
+		// 逐条加载后物理删除，触发删除钩子写入UUID墓碑
+		var files []*models.File
+		if err := tx.Unscoped().Where("id IN ?", fileIDs).Find(&files).Error; err != nil {
+			return err
+		}
+		if len(files) == 0 {
+			return nil
+		}
+		return tx.Unscoped().Delete(&files).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return reclaimed, nil
+}
+
+// escapeLike 转义LIKE模式中的通配符，配合 ESCAPE '!' 使用
+func escapeLike(value string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(value)
+}
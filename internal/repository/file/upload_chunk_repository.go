@@ -0,0 +1,38 @@
+package file
+
+import (
+	"context"
+	"time"
+
+	"cloudpan/internal/repository/models"
+)
+
+// UploadChunkRepository 分片上传数据仓库接口
+//
+// 提供分片上传任务中分片记录的数据访问操作，包括：
+// 1. 分片登记：按上传任务和分片索引保存分片，重传同一分片时覆盖原记录
+// 2. 断点续传：列出上传任务已接收的分片
+// 3. 清理：合并完成后删除分片记录，定期清理过期分片
+// 4. 崩溃恢复：按存储路径核对和删除分片记录
+//
+// 使用示例：
+//
+//	repo := NewUploadChunkRepository(db)
+//	err := repo.SaveChunk(ctx, chunk)
+//	chunks, err := repo.ListByUploadID(ctx, uploadID)
+//	expired, err := repo.ListExpired(ctx, time.Now(), 500)
+type UploadChunkRepository interface {
+	// 分片登记
+	SaveChunk(ctx context.Context, chunk *models.FileUploadChunk) error
+	ListByUploadID(ctx context.Context, uploadID string) ([]*models.FileUploadChunk, error)
+	ExtendExpiry(ctx context.Context, uploadID string, expiresAt time.Time) error
+
+	// 清理
+	DeleteByUploadID(ctx context.Context, uploadID string) error
+	ListExpired(ctx context.Context, before time.Time, limit int) ([]*models.FileUploadChunk, error)
+	DeleteByIDs(ctx context.Context, ids []uint) error
+
+	// 崩溃恢复
+	ExistsByStoragePath(ctx context.Context, storagePath string) (bool, error)
+	DeleteByStoragePath(ctx context.Context, storagePath string) (int64, error)
+}
@@ -0,0 +1,153 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/database"
+	"cloudpan/internal/repository/models"
+)
+
+// uploadChunkRepository 分片上传数据仓库实现
+type uploadChunkRepository struct {
+	db *gorm.DB
+}
+
+// NewUploadChunkRepository 创建分片上传数据仓库实例
+func NewUploadChunkRepository(db *gorm.DB) UploadChunkRepository {
+	return &uploadChunkRepository{
+		db: db,
+	}
+}
+
+// SaveChunk 保存分片记录，同一上传任务的同一分片已存在时覆盖
+func (r *uploadChunkRepository) SaveChunk(ctx context.Context, chunk *models.FileUploadChunk) error {
+	if chunk == nil || chunk.UploadID == "" {
+		return fmt.Errorf("上传任务ID不能为空")
+	}
+
+	return database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		var existing models.FileUploadChunk
+		err := tx.Where("upload_id = ? AND chunk_index = ?", chunk.UploadID, chunk.ChunkIndex).
+			First(&existing).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return tx.Create(chunk).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		chunk.ID = existing.ID
+		return tx.Model(&existing).UpdateColumns(map[string]interface{}{
+			"chunk_size":           chunk.ChunkSize,
+			"chunk_hash":           chunk.ChunkHash,
+			"chunk_hash_algorithm": chunk.ChunkHashAlgorithm,
+			"storage_path":         chunk.StoragePath,
+			"status":               chunk.Status,
+			"expires_at":           chunk.ExpiresAt,
+			"completed_at":         chunk.CompletedAt,
+		}).Error
+	})
+}
+
+// ListByUploadID 按分片索引顺序列出上传任务的分片
+func (r *uploadChunkRepository) ListByUploadID(ctx context.Context, uploadID string) ([]*models.FileUploadChunk, error) {
+	if uploadID == "" {
+		return nil, fmt.Errorf("上传任务ID不能为空")
+	}
+
+	var chunks []*models.FileUploadChunk
+	err := database.Conn(ctx, r.db).
+		Where("upload_id = ?", uploadID).
+		Order("chunk_index ASC").
+		Find(&chunks).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return chunks, nil
+}
+
+// ExtendExpiry 延长上传任务全部分片记录的过期时间，已晚于指定时间的记录不变
+func (r *uploadChunkRepository) ExtendExpiry(ctx context.Context, uploadID string, expiresAt time.Time) error {
+	if uploadID == "" {
+		return fmt.Errorf("上传任务ID不能为空")
+	}
+
+	return database.Conn(ctx, r.db).Model(&models.FileUploadChunk{}).
+		Where("upload_id = ? AND expires_at < ?", uploadID, expiresAt).
+		UpdateColumn("expires_at", expiresAt).Error
+}
+
+// DeleteByUploadID 删除上传任务的全部分片记录
+func (r *uploadChunkRepository) DeleteByUploadID(ctx context.Context, uploadID string) error {
+	if uploadID == "" {
+		return fmt.Errorf("上传任务ID不能为空")
+	}
+
+	return database.Conn(ctx, r.db).Unscoped().
+		Where("upload_id = ?", uploadID).
+		Delete(&models.FileUploadChunk{}).Error
+}
+
+// ListExpired 查询已过期的分片，按过期时间顺序返回
+func (r *uploadChunkRepository) ListExpired(ctx context.Context, before time.Time, limit int) ([]*models.FileUploadChunk, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("查询数量必须大于0")
+	}
+
+	var chunks []*models.FileUploadChunk
+	err := database.Conn(ctx, r.db).
+		Where("expires_at < ?", before).
+		Order("expires_at ASC").
+		Limit(limit).
+		Find(&chunks).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return chunks, nil
+}
+
+// DeleteByIDs 批量删除分片记录
+func (r *uploadChunkRepository) DeleteByIDs(ctx context.Context, ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	return database.Conn(ctx, r.db).Unscoped().
+		Where("id IN ?", ids).
+		Delete(&models.FileUploadChunk{}).Error
+}
+
+// ExistsByStoragePath 检查是否有分片记录引用指定的存储路径
+func (r *uploadChunkRepository) ExistsByStoragePath(ctx context.Context, storagePath string) (bool, error) {
+	if storagePath == "" {
+		return false, fmt.Errorf("存储路径不能为空")
+	}
+
+	var count int64
+	err := database.Conn(ctx, r.db).Model(&models.FileUploadChunk{}).
+		Where("storage_path = ?", storagePath).
+		Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// DeleteByStoragePath 删除引用指定存储路径的分片记录，返回删除的记录数
+func (r *uploadChunkRepository) DeleteByStoragePath(ctx context.Context, storagePath string) (int64, error) {
+	if storagePath == "" {
+		return 0, fmt.Errorf("存储路径不能为空")
+	}
+
+	result := database.Conn(ctx, r.db).Unscoped().
+		Where("storage_path = ?", storagePath).
+		Delete(&models.FileUploadChunk{})
+	return result.RowsAffected, result.Error
+}
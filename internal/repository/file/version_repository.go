@@ -0,0 +1,37 @@
+package file
+
+import (
+	"context"
+	"errors"
+
+	"cloudpan/internal/repository/models"
+)
+
+// ErrContentChanged 替换文件内容时文件内容已被并发修改
+var ErrContentChanged = errors.New("文件内容已被修改")
+
+// VersionRepository 文件历史版本数据仓库接口
+//
+// 提供文件历史版本的数据访问操作，包括：
+// 1. 查询：按文件列出历史版本(版本号降序)，按版本号查询单个版本
+// 2. 替换内容：事务内把文件当前内容登记为新的历史版本并写入新内容，恢复时同时删除被恢复的版本
+// 3. 删除：按ID物理删除存储对象已删除的版本，按文件列出版本以便彻底删除文件时回收存储空间
+//
+// 使用示例：
+//
+//	repo := NewVersionRepository(db)
+//	versions, err := repo.ListByFile(ctx, fileID)
+//	err = repo.ReplaceContent(ctx, file, oldPath, snapshot, nil)
+//	err = repo.Delete(ctx, []uint{version.ID})
+type VersionRepository interface {
+	// 查询
+	ListByFile(ctx context.Context, fileID uint) ([]*models.FileVersion, error)
+	GetByNumber(ctx context.Context, fileID uint, number int) (*models.FileVersion, error)
+	ListByFiles(ctx context.Context, fileIDs []uint) ([]*models.FileVersion, error)
+
+	// 替换内容
+	ReplaceContent(ctx context.Context, file *models.File, oldPath string, snapshot, restored *models.FileVersion) error
+
+	// 删除
+	Delete(ctx context.Context, ids []uint) error
+}
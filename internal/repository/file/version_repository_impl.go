@@ -0,0 +1,120 @@
+package file
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"cloudpan/internal/pkg/database"
+	"cloudpan/internal/repository/models"
+)
+
+// versionRepository 文件历史版本数据仓库实现
+type versionRepository struct {
+	db *gorm.DB
+}
+
+// NewVersionRepository 创建文件历史版本数据仓库实例
+func NewVersionRepository(db *gorm.DB) VersionRepository {
+	return &versionRepository{
+		db: db,
+	}
+}
+
+// ListByFile 获取文件的历史版本，按版本号降序排列
+func (r *versionRepository) ListByFile(ctx context.Context, fileID uint) ([]*models.FileVersion, error) {
+	var versions []*models.FileVersion
+	err := database.Conn(ctx, r.db).
+		Where("file_id = ?", fileID).
+		Order("version_number DESC").
+		Find(&versions).Error
+	return versions, err
+}
+
+// GetByNumber 按版本号获取文件的历史版本
+func (r *versionRepository) GetByNumber(ctx context.Context, fileID uint, number int) (*models.FileVersion, error) {
+	var version models.FileVersion
+	err := database.Conn(ctx, r.db).
+		Where("file_id = ? AND version_number = ?", fileID, number).
+		First(&version).Error
+	if err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+// ListByFiles 获取一组文件的全部历史版本
+func (r *versionRepository) ListByFiles(ctx context.Context, fileIDs []uint) ([]*models.FileVersion, error) {
+	if len(fileIDs) == 0 {
+		return nil, nil
+	}
+	var versions []*models.FileVersion
+	err := database.Conn(ctx, r.db).
+		Where("file_id IN ?", fileIDs).
+		Order("id").
+		Find(&versions).Error
+	return versions, err
+}
+
+// ReplaceContent 在一个事务中把文件原来的内容登记为历史版本并写入新内容
+//
+// snapshot 的版本号取该文件已有的最大版本号加一；restored 不为nil时表示从该版本恢复，
+// 其存储对象成为文件的当前内容，版本记录随之删除。
+// 文件的存储路径已不是 oldPath 时说明内容被并发替换，返回 ErrContentChanged 且不做任何修改
+func (r *versionRepository) ReplaceContent(ctx context.Context, file *models.File, oldPath string, snapshot, restored *models.FileVersion) error {
+	if file == nil || file.ID == 0 || snapshot == nil {
+		return fmt.Errorf("文件和版本不能为空")
+	}
+
+	return database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.File{}).
+			Where("id = ? AND storage_path = ?", file.ID, oldPath).
+			Updates(map[string]interface{}{
+				"size":           file.Size,
+				"hash":           file.Hash,
+				"hash_type":      file.HashType,
+				"storage_path":   file.StoragePath,
+				"mime_type":      file.MimeType,
+				"scan_status":    file.ScanStatus,
+				"preview_status": file.PreviewStatus,
+				"thumbnail_url":  nil,
+				"preview_url":    nil,
+				"version":        gorm.Expr("version + 1"),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrContentChanged
+		}
+
+		var latest int
+		err := tx.Model(&models.FileVersion{}).Unscoped().
+			Where("file_id = ?", file.ID).
+			Select("COALESCE(MAX(version_number), 0)").
+			Scan(&latest).Error
+		if err != nil {
+			return err
+		}
+		snapshot.FileID = file.ID
+		snapshot.VersionNumber = latest + 1
+		if err := tx.Omit(clause.Associations).Create(snapshot).Error; err != nil {
+			return err
+		}
+
+		if restored != nil {
+			return tx.Unscoped().Delete(&models.FileVersion{}, restored.ID).Error
+		}
+		return nil
+	})
+}
+
+// Delete 物理删除历史版本，调用方应先删除版本的存储对象
+func (r *versionRepository) Delete(ctx context.Context, ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return database.Conn(ctx, r.db).Unscoped().Where("id IN ?", ids).Delete(&models.FileVersion{}).Error
+}
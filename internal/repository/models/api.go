@@ -1,6 +1,8 @@
 package models
 
 import (
+	"slices"
+	"strings"
 	"time"
 
 	basemodels "cloudpan/internal/pkg/database/models"
@@ -360,6 +362,78 @@ func (al *APILog) IsSuccess() bool {
 	return al.StatusCode >= 200 && al.StatusCode < 300
 }
 
+// APIUsageDaily API用量日汇总表结构
+//
+// 按 用户 + API密钥 + 日期 + 接口 汇总请求数和流量，由内存计数定期累加写入；
+// APIKeyID为0表示通过登录会话发起的请求。日期按UTC划分并以YYYY-MM-DD字符串保存，
+// 不受数据库连接时区设置影响
+type APIUsageDaily struct {
+	basemodels.BaseModelWithoutSoftDelete
+	UserID   uint   `gorm:"not null;uniqueIndex:uk_api_usage_daily,priority:1" json:"user_id"`                    // 用户ID
+	Date     string `gorm:"type:char(10);not null;uniqueIndex:uk_api_usage_daily,priority:2;index" json:"date"`   // 统计日期(YYYY-MM-DD，UTC)
+	APIKeyID uint   `gorm:"not null;default:0;uniqueIndex:uk_api_usage_daily,priority:3" json:"api_key_id"`       // API密钥ID
+	Endpoint string `gorm:"type:varchar(255);not null;uniqueIndex:uk_api_usage_daily,priority:4" json:"endpoint"` // 接口(方法+路由模板)
+	Requests int64  `gorm:"not null;default:0" json:"requests"`                                                   // 请求数
+	Errors   int64  `gorm:"not null;default:0" json:"errors"`                                                     // 失败请求数(状态码>=400)
+	BytesIn  int64  `gorm:"not null;default:0" json:"bytes_in"`                                                   // 请求流量
+	BytesOut int64  `gorm:"not null;default:0" json:"bytes_out"`                                                  // 响应流量
+}
+
+// TableName API用量日汇总表名
+func (APIUsageDaily) TableName() string {
+	return "api_usage_daily"
+}
+
+// API密钥权限范围
+const (
+	APIKeyScopeRead  = "read"  // 查询和下载
+	APIKeyScopeWrite = "write" // 上传、修改和删除
+	APIKeyScopeShare = "share" // 创建和管理分享
+)
+
+// APIKeyScopes 全部API密钥权限范围
+var APIKeyScopes = []string{APIKeyScopeRead, APIKeyScopeWrite, APIKeyScopeShare}
+
+// APIKey 用户API密钥表结构
+//
+// 用户为脚本和第三方工具创建的访问密钥，请求时通过 X-API-Key 请求头认证。
+// 密钥明文只在创建时返回一次，表中保存SHA-256哈希和用于识别的前缀；
+// 权限范围以逗号分隔保存，撤销时删除记录
+type APIKey struct {
+	basemodels.BaseModelWithoutSoftDelete
+	UserID     uint       `gorm:"not null;index" json:"user_id"`                  // 所有者用户ID
+	Name       string     `gorm:"type:varchar(100);not null" json:"name"`         // 密钥名称
+	Prefix     string     `gorm:"type:varchar(16);not null" json:"prefix"`        // 密钥前缀，列表中用于识别密钥
+	KeyHash    string     `gorm:"type:char(64);not null;uniqueIndex" json:"-"`    // 密钥SHA-256哈希
+	Scopes     string     `gorm:"type:varchar(100);not null" json:"scopes"`       // 权限范围，逗号分隔
+	ExpiresAt  *time.Time `gorm:"index" json:"expires_at,omitempty"`              // 过期时间，为空表示不过期
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`                         // 最后使用时间
+	LastUsedIP *string    `gorm:"type:varchar(45)" json:"last_used_ip,omitempty"` // 最后使用的IP地址
+}
+
+// TableName API密钥表名
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// ScopeList 返回权限范围列表
+func (k *APIKey) ScopeList() []string {
+	if k.Scopes == "" {
+		return nil
+	}
+	return strings.Split(k.Scopes, ",")
+}
+
+// HasScope 检查是否具有指定权限范围
+func (k *APIKey) HasScope(scope string) bool {
+	return slices.Contains(k.ScopeList(), scope)
+}
+
+// IsExpired 检查是否已过期
+func (k *APIKey) IsExpired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
+
 // 应用类型常量
 const (
 	AppTypeWeb     = "web"     // Web应用
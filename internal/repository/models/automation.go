@@ -0,0 +1,84 @@
+package models
+
+import (
+	"time"
+
+	basemodels "cloudpan/internal/pkg/database/models"
+
+	"gorm.io/gorm"
+)
+
+// 文件夹规则动作类型
+const (
+	RuleActionWebhook = "webhook" // 调用Webhook
+	RuleActionTag     = "tag"     // 添加标签
+	RuleActionMove    = "move"    // 移动到其他文件夹
+)
+
+// 文件夹规则触发事件
+const (
+	RuleEventFileUploaded = "file.uploaded" // 文件上传完成
+)
+
+// 文件夹规则执行结果
+const (
+	RuleLogStatusSuccess = "success" // 执行成功
+	RuleLogStatusFailed  = "failed"  // 执行失败
+)
+
+// FolderRule 文件夹自动化规则表结构
+//
+// 文件上传到规则所在的文件夹(启用 IncludeSubfolders 时包括子文件夹)后，
+// 条件表达式匹配时执行一个动作：调用Webhook、添加标签或移动到其他文件夹
+type FolderRule struct {
+	basemodels.BaseModel
+	// 基本信息
+	UserID            uint       `gorm:"not null;index" json:"user_id"`                                  // 用户ID
+	FolderID          uint       `gorm:"not null;index" json:"folder_id"`                                // 规则所在文件夹ID
+	Name              string     `gorm:"type:varchar(100);not null" json:"name"`                         // 规则名称
+	Event             string     `gorm:"type:varchar(50);not null;default:'file.uploaded'" json:"event"` // 触发事件
+	Condition         string     `gorm:"type:varchar(1000);not null;default:''" json:"condition"`        // 条件表达式，为空时总是执行
+	IncludeSubfolders bool       `gorm:"default:false" json:"include_subfolders"`                        // 是否对子文件夹中的文件生效
+	IsActive          bool       `gorm:"default:true;index" json:"is_active"`                            // 是否启用
+	Action            string     `gorm:"type:varchar(20);not null" json:"action"`                        // 动作类型
+	WebhookURL        string     `gorm:"type:varchar(500)" json:"webhook_url,omitempty"`                 // Webhook地址
+	WebhookSecret     string     `gorm:"type:varchar(255)" json:"-"`                                     // Webhook签名密钥，不返回给客户端
+	Tags              string     `gorm:"type:varchar(500)" json:"tags,omitempty"`                        // 要添加的标签(逗号分隔)
+	TargetFolderID    *uint      `gorm:"index" json:"target_folder_id,omitempty"`                        // 移动的目标文件夹ID，为空时移动到根目录
+	WebhookSigned     bool       `gorm:"-" json:"webhook_signed,omitempty"`                              // 是否设置了签名密钥
+	LastStatus        string     `gorm:"type:varchar(20)" json:"last_status,omitempty"`                  // 最后一次执行结果
+	TriggerCount      int64      `gorm:"default:0" json:"trigger_count"`                                 // 执行次数
+	FailureCount      int64      `gorm:"default:0" json:"failure_count"`                                 // 失败次数
+	LastTriggeredAt   *time.Time `json:"last_triggered_at,omitempty"`                                    // 最后执行时间
+}
+
+// TableName 文件夹规则表名
+func (FolderRule) TableName() string {
+	return "folder_rules"
+}
+
+// AfterFind 查询后标记是否设置了签名密钥
+func (r *FolderRule) AfterFind(tx *gorm.DB) error {
+	r.WebhookSigned = r.WebhookSecret != ""
+	return nil
+}
+
+// FolderRuleLog 文件夹规则执行日志表结构
+//
+// 条件匹配并执行动作后记录一条日志，条件不匹配的文件不记录
+type FolderRuleLog struct {
+	ID         uint      `gorm:"primarykey" json:"id"`
+	RuleID     uint      `gorm:"not null;index:idx_folder_rule_logs_rule,priority:1" json:"rule_id"`          // 规则ID
+	FileID     uint      `gorm:"not null;index" json:"file_id"`                                               // 触发规则的文件ID
+	FileName   string    `gorm:"type:varchar(255);not null" json:"file_name"`                                 // 触发时的文件名
+	Action     string    `gorm:"type:varchar(20);not null" json:"action"`                                     // 动作类型
+	Status     string    `gorm:"type:varchar(20);not null" json:"status"`                                     // 执行结果
+	Message    string    `gorm:"type:varchar(1000)" json:"message,omitempty"`                                 // 结果说明，如Webhook响应状态码或错误原因
+	DurationMs int64     `gorm:"default:0" json:"duration_ms"`                                                // 执行耗时(毫秒)
+	CreatedAt  time.Time `gorm:"not null;index;index:idx_folder_rule_logs_rule,priority:2" json:"created_at"` // 执行时间
+}
+
+// TableName 文件夹规则执行日志表名
+func (FolderRuleLog) TableName() string {
+	return "folder_rule_logs"
+}
@@ -67,6 +67,17 @@ func TestUserModelMethods(t *testing.T) {
 
 		user.Status = basemodels.StatusActive
 		assert.False(t, user.IsSuspended())
+
+		// Test IsPendingDeletion
+		now := time.Now()
+		scheduledAt := now.Add(time.Hour)
+		user.DeletionScheduledAt = &scheduledAt
+		assert.False(t, user.IsPendingDeletion(now))
+		user.Status = "deleted"
+		assert.True(t, user.IsPendingDeletion(now))
+		assert.False(t, user.IsPendingDeletion(scheduledAt))
+		user.Status = basemodels.StatusActive
+		user.DeletionScheduledAt = nil
 	})
 
 	t.Run("Storage Methods", func(t *testing.T) {
@@ -374,7 +385,7 @@ func TestTeamMembershipModelMethods(t *testing.T) {
 		assert.False(t, membership.CanManageMembers())
 		assert.True(t, membership.CanManageFiles())
 
-		membership.Role = "readonly"
+		membership.Role = "viewer"
 		assert.False(t, membership.IsOwner())
 		assert.False(t, membership.IsAdmin())
 		assert.False(t, membership.CanManageMembers())
@@ -1,9 +1,13 @@
 package models
 
 import (
+	"crypto/subtle"
+	"strings"
 	"time"
 
 	basemodels "cloudpan/internal/pkg/database/models"
+	"cloudpan/internal/pkg/indexing"
+	"cloudpan/internal/pkg/utils"
 
 	"gorm.io/gorm"
 )
@@ -12,14 +16,14 @@ import (
 type File struct {
 	basemodels.BaseModel
 	// 基本信息
-	UUID     string `gorm:"type:char(36);uniqueIndex;not null" json:"uuid"` // 文件唯一标识符
-	UserID   uint   `gorm:"not null;index" json:"user_id"`                  // 所属用户ID
-	ParentID *uint  `gorm:"index" json:"parent_id,omitempty"`               // 父文件夹ID
-	Name     string `gorm:"type:varchar(255);not null" json:"name"`         // 文件名
-	Path     string `gorm:"type:varchar(2000);not null;index" json:"path"`  // 文件路径
+	UUID     string `gorm:"type:char(36);uniqueIndex;not null" json:"uuid"`                            // 文件唯一标识符
+	UserID   uint   `gorm:"not null;index;index:idx_files_listing,priority:1" json:"user_id"`          // 所属用户ID
+	ParentID *uint  `gorm:"index;index:idx_files_listing,priority:2" json:"parent_id,omitempty"`       // 父文件夹ID
+	Name     string `gorm:"type:varchar(255);not null;index:idx_files_listing,priority:4" json:"name"` // 文件名
+	Path     string `gorm:"type:varchar(2000);not null;index" json:"path"`                             // 文件路径
 
 	// 文件类型和内容信息
-	IsFolder  bool    `gorm:"default:false;index" json:"is_folder"`                                      // 是否为文件夹
+	IsFolder  bool    `gorm:"default:false;index;index:idx_files_listing,priority:3" json:"is_folder"`   // 是否为文件夹
 	MimeType  *string `gorm:"type:varchar(255)" json:"mime_type,omitempty"`                              // MIME类型
 	Extension *string `gorm:"type:varchar(50)" json:"extension,omitempty"`                               // 文件扩展名
 	Size      int64   `gorm:"default:0" json:"size"`                                                     // 文件大小(字节)
@@ -31,6 +35,12 @@ type File struct {
 	StoragePath   *string `gorm:"type:varchar(2000)" json:"storage_path,omitempty"`                          // 实际存储路径
 	StorageBucket *string `gorm:"type:varchar(255)" json:"storage_bucket,omitempty"`                         // 存储桶名称
 
+	// 归档存储
+	StorageClass       string     `gorm:"type:varchar(32);default:'STANDARD'" json:"storage_class"` // 对象存储类型
+	ArchivedAt         *time.Time `json:"archived_at,omitempty"`                                    // 归档时间(为空表示未归档)
+	RestoreRequestedAt *time.Time `json:"restore_requested_at,omitempty"`                           // 最近一次发起恢复的时间
+	RestoredUntil      *time.Time `json:"restored_until,omitempty"`                                 // 恢复副本可读取的截止时间
+
 	// 安全和权限
 	IsEncrypted   bool    `gorm:"default:false" json:"is_encrypted"`                                            // 是否加密
 	EncryptionKey *string `gorm:"type:varchar(255)" json:"-"`                                                   // 加密密钥(不返回)
@@ -42,6 +52,10 @@ type File struct {
 	ThumbnailURL *string `gorm:"type:varchar(500)" json:"thumbnail_url,omitempty"`                                               // 缩略图URL
 	PreviewURL   *string `gorm:"type:varchar(500)" json:"preview_url,omitempty"`                                                 // 预览URL
 
+	// 处理流水线状态，通过 Processing 汇总后返回给客户端
+	ScanStatus    string `gorm:"type:varchar(20);not null;default:'pending'" json:"-"` // 病毒扫描状态
+	PreviewStatus string `gorm:"type:varchar(20);not null;default:''" json:"-"`        // 预览生成状态，为空表示不生成预览
+
 	// 元数据
 	Metadata    *basemodels.JSONMap `gorm:"type:json" json:"metadata,omitempty"`      // 文件元数据
 	Tags        *string             `gorm:"type:varchar(1000)" json:"tags,omitempty"` // 标签(逗号分隔)
@@ -52,9 +66,16 @@ type File struct {
 	ViewCount     int64 `gorm:"default:0" json:"view_count"`     // 查看次数
 	ShareCount    int64 `gorm:"default:0" json:"share_count"`    // 分享次数
 
+	// 完整性校验
+	ContentChecksum   *string    `gorm:"type:varchar(64)" json:"content_checksum,omitempty"` // 文件夹子树组合校验和(为空表示需要重新计算)
+	ChecksumUpdatedAt *time.Time `json:"checksum_updated_at,omitempty"`                      // 校验和计算时间
+
 	// 时间信息
 	LastAccessedAt *time.Time `json:"last_accessed_at,omitempty"` // 最后访问时间
 
+	// 上传进度，只在文件夹列表中为上传占位记录填充，不写入数据库
+	UploadProgress *FileUploadProgress `gorm:"-" json:"upload_progress,omitempty"`
+
 	// 关联关系
 	Owner        User              `gorm:"foreignKey:UserID" json:"owner,omitempty"`
 	Parent       *File             `gorm:"foreignKey:ParentID" json:"parent,omitempty"`
@@ -70,19 +91,163 @@ func (File) TableName() string {
 	return "files"
 }
 
+// BeforeSave 保存前钩子，规范化并校验写入的文件名
+//
+// 创建、Save和Updates都会经过该钩子，保证任何写入路径都无法保存
+// 路径穿越、控制字符或保留设备名等不安全的名称；Updates未更新名称时跳过
+func (f *File) BeforeSave(tx *gorm.DB) error {
+	name, ok := fileNameFromDest(tx.Statement.Dest, f)
+	if !ok {
+		return nil
+	}
+
+	normalized, err := utils.NormalizeFileName(name)
+	if err != nil {
+		return err
+	}
+	if normalized != name {
+		tx.Statement.SetColumn("Name", normalized)
+	}
+	return nil
+}
+
+// fileNameFromDest 获取本次写入的文件名，未写入名称时返回false
+func fileNameFromDest(dest interface{}, f *File) (string, bool) {
+	switch d := dest.(type) {
+	case map[string]interface{}:
+		for _, key := range []string{"name", "Name"} {
+			if value, exists := d[key]; exists {
+				name, isString := value.(string)
+				return name, isString
+			}
+		}
+		return "", false
+	case *File:
+		// Updates(&File{...}) 忽略零值字段，名称为空表示不更新名称
+		if d != f && d.Name == "" {
+			return "", false
+		}
+		return d.Name, true
+	case File:
+		if d.Name == "" {
+			return "", false
+		}
+		return d.Name, true
+	case []*File, []File:
+		return f.Name, true
+	}
+	return "", false
+}
+
 // BeforeCreate 创建前钩子
+//
+// UUID需要在包括软删除记录和墓碑在内的范围内全局唯一
 func (f *File) BeforeCreate(tx *gorm.DB) error {
-	if f.UUID == "" {
-		f.UUID = basemodels.GenerateUUID()
+	uuid, err := ensureUniqueIdentifier(tx, TombstoneKindFileUUID, &File{}, "uuid", f.UUID, basemodels.GenerateUUID)
+	if err != nil {
+		return err
 	}
+	f.UUID = uuid
 	return f.BaseModel.BeforeCreate(tx)
 }
 
+// AfterCreate 创建后钩子，暂存索引文档
+func (f *File) AfterCreate(tx *gorm.DB) error {
+	return indexing.StageUpsert(tx, f.ID, &File{})
+}
+
+// AfterUpdate 更新后钩子，重新加载并暂存索引文档
+func (f *File) AfterUpdate(tx *gorm.DB) error {
+	return indexing.StageUpsert(tx, f.ID, &File{})
+}
+
+// AfterDelete 删除后钩子，暂存删除文档；物理删除时将UUID写入墓碑表
+func (f *File) AfterDelete(tx *gorm.DB) error {
+	indexing.StageDelete(tx, indexing.DocumentTypeFile, f.ID, f.Version)
+	if !tx.Statement.Unscoped {
+		return nil
+	}
+	return RetireIdentifier(tx, TombstoneKindFileUUID, f.UUID, f.TableName(), f.ID)
+}
+
+// IndexDocument 生成文件索引文档
+func (f *File) IndexDocument() indexing.Document {
+	var tags []string
+	if f.Tags != nil {
+		for _, tag := range strings.Split(*f.Tags, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+	}
+
+	return indexing.Document{
+		Type:      indexing.DocumentTypeFile,
+		ID:        f.ID,
+		Operation: indexing.OperationUpsert,
+		Version:   f.Version,
+		Fields: map[string]interface{}{
+			"uuid":         f.UUID,
+			"user_id":      f.UserID,
+			"parent_id":    f.ParentID,
+			"name":         f.Name,
+			"path":         f.Path,
+			"is_folder":    f.IsFolder,
+			"mime_type":    stringValue(f.MimeType),
+			"extension":    stringValue(f.Extension),
+			"size":         f.Size,
+			"status":       f.Status,
+			"access_level": f.AccessLevel,
+			"tags":         tags,
+			"description":  stringValue(f.Description),
+			"updated_at":   f.UpdatedAt,
+		},
+		OccurredAt: time.Now(),
+	}
+}
+
 // IsActive 检查文件是否活动
 func (f *File) IsActive() bool {
 	return f.Status == "active"
 }
 
+// IsUploadPlaceholder 检查是否为进行中的分片上传占位记录
+//
+// 分片上传申请时即登记文件记录，上传完成前在文件夹列表中显示进度，其他设备可以看到正在上传的文件
+func (f *File) IsUploadPlaceholder() bool {
+	return f.Status == FileStatusUploading && f.UploadStatus == UploadStatusUploading
+}
+
+// 归档状态
+const (
+	ArchiveStateAvailable = "available" // 未归档，可直接读取
+	ArchiveStateArchived  = "archived"  // 已归档，需要先发起恢复
+	ArchiveStateRestoring = "restoring" // 恢复中
+	ArchiveStateRestored  = "restored"  // 已恢复，在restored_until之前可读取
+)
+
+// ArchiveState 返回文件在指定时间的归档状态
+//
+// 恢复副本过期后回到archived；过期后再次发起恢复时发起时间晚于旧的截止时间，状态为restoring
+func (f *File) ArchiveState(now time.Time) string {
+	switch {
+	case f.ArchivedAt == nil:
+		return ArchiveStateAvailable
+	case f.RestoredUntil != nil && now.Before(*f.RestoredUntil):
+		return ArchiveStateRestored
+	case f.RestoreRequestedAt != nil && (f.RestoredUntil == nil || f.RestoreRequestedAt.After(*f.RestoredUntil)):
+		return ArchiveStateRestoring
+	default:
+		return ArchiveStateArchived
+	}
+}
+
+// IsReadable 检查文件内容当前是否可读取(未归档或已恢复)
+func (f *File) IsReadable(now time.Time) bool {
+	state := f.ArchiveState(now)
+	return state == ArchiveStateAvailable || state == ArchiveStateRestored
+}
+
 // IsImage 检查是否为图片文件
 func (f *File) IsImage() bool {
 	if f.MimeType == nil {
@@ -156,21 +321,29 @@ type FileShare struct {
 
 	// 权限设置
 	Permission  string  `gorm:"type:enum('view','download','edit');default:'view'" json:"permission"` // 权限类型
-	Password    *string `gorm:"type:varchar(255)" json:"-"`                                           // 分享密码(加密存储)
+	Password    *string `gorm:"type:varchar(255)" json:"-"`                                           // 分享密码(BCrypt哈希)
 	HasPassword bool    `gorm:"default:false" json:"has_password"`                                    // 是否设置密码
 
+	// 密码尝试保护
+	PasswordFailedAttempts int        `gorm:"default:0" json:"-"`              // 连续密码错误次数
+	PasswordLockedUntil    *time.Time `json:"password_locked_until,omitempty"` // 密码验证锁定截止时间
+
 	// 访问控制
 	MaxAccess     *int `json:"max_access,omitempty"`            // 最大访问次数
 	AccessCount   int  `gorm:"default:0" json:"access_count"`   // 已访问次数
 	MaxDownload   *int `json:"max_download,omitempty"`          // 最大下载次数
 	DownloadCount int  `gorm:"default:0" json:"download_count"` // 已下载次数
 
+	// 流量控制
+	TransferUsed        int64      `gorm:"default:0" json:"transfer_used"`  // 当前周期已消耗的下载流量(字节)
+	TransferPeriodStart *time.Time `json:"transfer_period_start,omitempty"` // 当前流量统计周期的开始时间
+
 	// 时间控制
 	ExpiresAt      *time.Time `json:"expires_at,omitempty"`       // 过期时间
 	LastAccessedAt *time.Time `json:"last_accessed_at,omitempty"` // 最后访问时间
 
 	// 状态
-	Status string `gorm:"type:enum('active','expired','disabled','deleted');default:'active'" json:"status"` // 分享状态
+	Status string `gorm:"type:enum('active','expired','disabled','suspended','deleted');default:'active'" json:"status"` // 分享状态
 
 	// 元数据
 	Settings *basemodels.JSONMap `gorm:"type:json" json:"settings,omitempty"` // 分享设置
@@ -186,13 +359,83 @@ func (FileShare) TableName() string {
 }
 
 // BeforeCreate 创建前钩子
+//
+// 分享码需要在包括软删除记录和墓碑在内的范围内全局唯一，冲突时重新生成
 func (s *FileShare) BeforeCreate(tx *gorm.DB) error {
-	if s.ShareCode == "" {
-		s.ShareCode = basemodels.GenerateShareCode()
+	code, err := ensureUniqueIdentifier(tx, TombstoneKindShareCode, &FileShare{}, "share_code", s.ShareCode, basemodels.GenerateShareCode)
+	if err != nil {
+		return err
 	}
+	s.ShareCode = code
 	return s.BaseModel.BeforeCreate(tx)
 }
 
+// BeforeSave 保存前钩子，将分享密码哈希后存储
+//
+// 已经是BCrypt哈希的值保持不变，空密码视为取消密码
+func (s *FileShare) BeforeSave(tx *gorm.DB) error {
+	if s.Password == nil {
+		return nil
+	}
+	if *s.Password == "" {
+		s.Password = nil
+		s.HasPassword = false
+		return nil
+	}
+	if !utils.IsPasswordHash(*s.Password) {
+		hashed, err := utils.HashPassword(*s.Password)
+		if err != nil {
+			return err
+		}
+		s.Password = &hashed
+	}
+	s.HasPassword = true
+	return nil
+}
+
+// CheckPassword 校验分享密码
+//
+// 兼容历史遗留的明文密码：明文匹配时返回 needsRehash=true，调用方应重新保存以升级为哈希
+func (s *FileShare) CheckPassword(password string) (ok bool, needsRehash bool) {
+	if !s.HasPassword || s.Password == nil || *s.Password == "" {
+		return true, false
+	}
+	if utils.IsPasswordHash(*s.Password) {
+		return utils.VerifyPassword(*s.Password, password), false
+	}
+	if subtle.ConstantTimeCompare([]byte(*s.Password), []byte(password)) == 1 {
+		return true, true
+	}
+	return false, false
+}
+
+// IsPasswordLocked 检查分享是否因密码错误次数过多被临时锁定
+func (s *FileShare) IsPasswordLocked(now time.Time) bool {
+	return s.PasswordLockedUntil != nil && now.Before(*s.PasswordLockedUntil)
+}
+
+// TransferPeriodStart 返回时间所在的流量统计周期(自然月，UTC)的开始时间
+func TransferPeriodStart(now time.Time) time.Time {
+	now = now.UTC()
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// CurrentTransferUsed 返回当前统计周期内已消耗的流量，记录属于过去周期时视为0
+func (s *FileShare) CurrentTransferUsed(now time.Time) int64 {
+	if s.TransferPeriodStart == nil || s.TransferPeriodStart.Before(TransferPeriodStart(now)) {
+		return 0
+	}
+	return s.TransferUsed
+}
+
+// AfterDelete 删除后钩子，物理删除时将分享码写入墓碑表
+func (s *FileShare) AfterDelete(tx *gorm.DB) error {
+	if !tx.Statement.Unscoped {
+		return nil
+	}
+	return RetireIdentifier(tx, TombstoneKindShareCode, s.ShareCode, s.TableName(), s.ID)
+}
+
 // IsExpired 检查是否过期
 func (s *FileShare) IsExpired() bool {
 	if s.ExpiresAt == nil {
@@ -215,6 +458,118 @@ func (s *FileShare) IsAccessible() bool {
 	return true
 }
 
+// 分享状态常量
+const (
+	ShareStatusActive    = "active"    // 有效
+	ShareStatusExpired   = "expired"   // 已过期
+	ShareStatusDisabled  = "disabled"  // 已停用(分享者撤销或管理员确认违规)
+	ShareStatusSuspended = "suspended" // 被举报次数达到阈值后暂停，等待管理员审核
+	ShareStatusDeleted   = "deleted"   // 已删除
+)
+
+// ShareAbuseReport 公开分享举报表结构
+//
+// 访问者举报违规分享，管理员审核后驳回(恢复被暂停的分享)或确认违规(停用分享)，
+// 同一分享的待审核举报一起处理
+type ShareAbuseReport struct {
+	basemodels.BaseModelWithoutSoftDelete
+	ShareID    uint    `gorm:"not null;index:idx_share_abuse_reports_share_status,priority:1" json:"share_id"` // 分享ID
+	SharerID   uint    `gorm:"not null;index" json:"sharer_id"`                                                // 分享者ID
+	ShareCode  string  `gorm:"type:varchar(100);not null" json:"share_code"`                                   // 举报时的分享码
+	Reason     string  `gorm:"type:varchar(20);not null" json:"reason"`                                        // 举报原因类别
+	Details    *string `gorm:"type:varchar(1000)" json:"details,omitempty"`                                    // 补充说明
+	ReporterID *uint   `gorm:"index" json:"reporter_id,omitempty"`                                             // 举报人ID(未登录时为空)
+	ReporterIP string  `gorm:"type:varchar(45);not null" json:"reporter_ip"`                                   // 举报人IP
+
+	// 审核信息
+	Status     string     `gorm:"type:varchar(20);not null;default:'pending';index:idx_share_abuse_reports_share_status,priority:2;index:idx_share_abuse_reports_status" json:"status"` // 审核状态
+	ReviewedBy *uint      `json:"reviewed_by,omitempty"`                                                                                                                                // 审核管理员ID
+	ReviewedAt *time.Time `json:"reviewed_at,omitempty"`                                                                                                                                // 审核时间
+	ReviewNote *string    `gorm:"type:varchar(500)" json:"review_note,omitempty"`                                                                                                       // 审核备注
+}
+
+// TableName 公开分享举报表名
+func (ShareAbuseReport) TableName() string {
+	return "share_abuse_reports"
+}
+
+// 举报原因类别
+const (
+	ShareAbuseReasonMalware   = "malware"   // 病毒或恶意软件
+	ShareAbuseReasonPhishing  = "phishing"  // 钓鱼或诈骗
+	ShareAbuseReasonCopyright = "copyright" // 侵犯版权
+	ShareAbuseReasonIllegal   = "illegal"   // 违法内容
+	ShareAbuseReasonSpam      = "spam"      // 垃圾广告
+	ShareAbuseReasonOther     = "other"     // 其他
+)
+
+// ShareAbuseReasons 全部举报原因类别
+var ShareAbuseReasons = []string{
+	ShareAbuseReasonMalware,
+	ShareAbuseReasonPhishing,
+	ShareAbuseReasonCopyright,
+	ShareAbuseReasonIllegal,
+	ShareAbuseReasonSpam,
+	ShareAbuseReasonOther,
+}
+
+// 举报审核状态
+const (
+	ShareAbuseStatusPending   = "pending"   // 待审核
+	ShareAbuseStatusDismissed = "dismissed" // 已驳回，未发现违规
+	ShareAbuseStatusActioned  = "actioned"  // 已确认违规，分享已停用
+)
+
+// ContentModerationResult 公开分享图片的内容审核结果
+//
+// 每个文件保留一条结果，文件内容(哈希)变化后重新审核并清除管理员的复核结论；
+// 管理员复核后以复核结论为准(放行或禁止公开分享)
+type ContentModerationResult struct {
+	basemodels.BaseModelWithoutSoftDelete
+	FileID    uint                `gorm:"not null;uniqueIndex" json:"file_id"`                                                 // 文件ID
+	UserID    uint                `gorm:"not null;index" json:"user_id"`                                                       // 文件所有者ID
+	FileName  string              `gorm:"type:varchar(255);not null" json:"file_name"`                                         // 审核时的文件名
+	FileHash  string              `gorm:"type:varchar(255);not null;default:''" json:"-"`                                      // 审核时的文件哈希
+	Status    string              `gorm:"type:varchar(20);not null;index:idx_content_moderation_results_status" json:"status"` // 审核结论
+	Label     string              `gorm:"type:varchar(50);not null;default:''" json:"label"`                                   // 置信度最高的标签
+	Score     float64             `gorm:"not null;default:0" json:"score"`                                                     // 最高置信度
+	Labels    *basemodels.JSONMap `gorm:"type:json" json:"labels,omitempty"`                                                   // 全部标签的置信度
+	ScannedAt time.Time           `gorm:"not null" json:"scanned_at"`                                                          // 审核时间
+
+	// 管理员复核
+	Override   *string    `gorm:"type:varchar(20)" json:"override,omitempty"`     // 复核结论(approved/rejected)
+	ReviewedBy *uint      `json:"reviewed_by,omitempty"`                          // 复核管理员ID
+	ReviewedAt *time.Time `json:"reviewed_at,omitempty"`                          // 复核时间
+	ReviewNote *string    `gorm:"type:varchar(500)" json:"review_note,omitempty"` // 复核备注
+}
+
+// TableName 内容审核结果表名
+func (ContentModerationResult) TableName() string {
+	return "content_moderation_results"
+}
+
+// Blocked 检查是否禁止公开分享，管理员复核结论优先于自动审核结论
+func (r *ContentModerationResult) Blocked() bool {
+	if r.Override != nil {
+		return *r.Override == ModerationOverrideRejected
+	}
+	return r.Status == ModerationStatusBlocked
+}
+
+// 内容审核结论
+const (
+	ModerationStatusClean   = "clean"   // 未发现违规
+	ModerationStatusFlagged = "flagged" // 疑似违规，允许分享但需要管理员复核
+	ModerationStatusBlocked = "blocked" // 违规，禁止公开分享
+	ModerationStatusFailed  = "failed"  // 审核接口不可用，允许分享，下次分享时重新审核
+)
+
+// 管理员复核结论
+const (
+	ModerationOverrideApproved = "approved" // 放行：允许公开分享
+	ModerationOverrideRejected = "rejected" // 禁止：停用文件的有效分享，不能再公开分享
+)
+
 // FileTag 文件标签表结构
 type FileTag struct {
 	basemodels.BaseModel
@@ -234,16 +589,42 @@ func (FileTag) TableName() string {
 	return "file_tags"
 }
 
-// BeforeCreate 创建前钩子
-func (t *FileTag) BeforeCreate(tx *gorm.DB) error {
-	// 确保同一用户对同一文件的同一标签唯一
-	var count int64
-	tx.Model(&FileTag{}).Where("file_id = ? AND user_id = ? AND tag = ?",
-		t.FileID, t.UserID, t.Tag).Count(&count)
-	if count > 0 {
-		return gorm.ErrDuplicatedKey
-	}
-	return t.BaseModel.BeforeCreate(tx)
+// RetainedObject 删除后保留的存储对象表结构
+//
+// 回收站项目彻底删除后文件记录已物理删除，存储对象按保留策略额外保留到DeleteAfter，
+// 保留期内管理员可以根据这里的信息重新创建文件记录
+type RetainedObject struct {
+	basemodels.BaseModelWithoutSoftDelete
+	UserID       uint    `gorm:"not null;index" json:"user_id"`                    // 原文件所属用户ID
+	FileID       uint    `gorm:"not null;uniqueIndex" json:"file_id"`              // 原文件ID(文件记录已删除)
+	Name         string  `gorm:"type:varchar(255);not null" json:"name"`           // 原文件名
+	OriginalPath string  `gorm:"type:varchar(2000);not null" json:"original_path"` // 原完整路径
+	Size         int64   `gorm:"not null;default:0" json:"size"`                   // 文件大小(字节)
+	MimeType     *string `gorm:"type:varchar(255)" json:"mime_type,omitempty"`     // MIME类型
+	Hash         *string `gorm:"type:varchar(255)" json:"hash,omitempty"`          // 文件哈希值
+	HashType     *string `gorm:"type:varchar(16)" json:"hash_type,omitempty"`      // 哈希类型
+
+	// 存储信息，恢复时原样写入新的文件记录
+	StorageType   string  `gorm:"type:varchar(16);not null" json:"storage_type"`                  // 存储类型
+	StoragePath   string  `gorm:"type:varchar(2000);not null" json:"storage_path"`                // 存储路径
+	StorageBucket *string `gorm:"type:varchar(255)" json:"storage_bucket,omitempty"`              // 存储桶名称
+	ScanStatus    string  `gorm:"type:varchar(20);not null;default:'pending'" json:"scan_status"` // 病毒扫描状态
+	IsEncrypted   bool    `gorm:"default:false" json:"is_encrypted"`                              // 是否加密
+	EncryptionKey *string `gorm:"type:varchar(255)" json:"-"`                                     // 加密密钥(不返回)
+
+	// 保留期
+	PurgedAt    time.Time `gorm:"not null" json:"purged_at"`          // 文件记录删除时间
+	DeleteAfter time.Time `gorm:"not null;index" json:"delete_after"` // 存储对象删除时间
+
+	// 恢复信息，恢复后存储对象归新的文件记录所有，不再按保留期删除
+	RestoredFileID *uint      `json:"restored_file_id,omitempty"` // 恢复后的文件ID
+	RestoredBy     *uint      `json:"restored_by,omitempty"`      // 执行恢复的管理员ID
+	RestoredAt     *time.Time `json:"restored_at,omitempty"`      // 恢复时间
+}
+
+// TableName 删除后保留的存储对象表名
+func (RetainedObject) TableName() string {
+	return "retained_objects"
 }
 
 // FileUploadChunk 文件分片上传表结构
@@ -260,10 +641,11 @@ type FileUploadChunk struct {
 	MimeType *string `gorm:"type:varchar(255)" json:"mime_type,omitempty"` // MIME类型
 
 	// 分片信息
-	ChunkIndex  int    `gorm:"not null" json:"chunk_index"`                  // 分片索引(从0开始)
-	ChunkSize   int64  `gorm:"not null" json:"chunk_size"`                   // 分片大小
-	ChunkHash   string `gorm:"type:varchar(255);not null" json:"chunk_hash"` // 分片哈希值
-	TotalChunks int    `gorm:"not null" json:"total_chunks"`                 // 总分片数
+	ChunkIndex         int    `gorm:"not null" json:"chunk_index"`                                         // 分片索引(从0开始)
+	ChunkSize          int64  `gorm:"not null" json:"chunk_size"`                                          // 分片大小
+	ChunkHash          string `gorm:"type:varchar(255);not null" json:"chunk_hash"`                        // 分片哈希值
+	ChunkHashAlgorithm string `gorm:"type:enum('md5','crc32c');default:'md5'" json:"chunk_hash_algorithm"` // 分片校验算法(同一上传任务内保持一致)
+	TotalChunks        int    `gorm:"not null" json:"total_chunks"`                                        // 总分片数
 
 	// 存储信息
 	StoragePath string `gorm:"type:varchar(2000);not null" json:"storage_path"`                           // 分片存储路径
@@ -291,6 +673,9 @@ func (c *FileUploadChunk) BeforeCreate(tx *gorm.DB) error {
 	if c.ExpiresAt.IsZero() {
 		c.ExpiresAt = time.Now().Add(24 * time.Hour) // 默认24小时过期
 	}
+	if c.ChunkHashAlgorithm == "" {
+		c.ChunkHashAlgorithm = ChunkHashAlgorithmMD5 // 兼容旧客户端，默认MD5
+	}
 	return c.BaseModel.BeforeCreate(tx)
 }
 
@@ -321,6 +706,21 @@ const (
 	UploadStatusFailed    = "failed"    // 上传失败
 )
 
+// FileUploadProgress 上传占位记录的上传进度
+type FileUploadProgress struct {
+	ReceivedChunks int       `json:"received_chunks"` // 已接收分片数
+	TotalChunks    int       `json:"total_chunks"`    // 总分片数
+	ReceivedBytes  int64     `json:"received_bytes"`  // 已接收字节数
+	TotalBytes     int64     `json:"total_bytes"`     // 文件总字节数
+	ExpiresAt      time.Time `json:"expires_at"`      // 上传任务过期时间，过期未完成的占位记录被清理
+}
+
+// 分片校验算法常量
+const (
+	ChunkHashAlgorithmMD5    = "md5"    // MD5(旧版协议默认)
+	ChunkHashAlgorithmCRC32C = "crc32c" // CRC32C(Castagnoli，推荐)
+)
+
 // 存储类型常量
 const (
 	StorageTypeLocal = "local" // 本地存储
@@ -342,3 +742,11 @@ const (
 	SharePermissionDownload = "download" // 可下载
 	SharePermissionEdit     = "edit"     // 可编辑
 )
+
+// stringValue 返回字符串指针的值，nil返回空字符串
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
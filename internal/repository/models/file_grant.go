@@ -0,0 +1,73 @@
+package models
+
+import (
+	"strings"
+
+	basemodels "cloudpan/internal/pkg/database/models"
+)
+
+// 文件授权权限
+const (
+	FilePermissionRead   = "read"   // 查看、下载和浏览文件夹
+	FilePermissionWrite  = "write"  // 重命名、移动、复制和在文件夹中新建文件夹
+	FilePermissionShare  = "share"  // 创建分享链接
+	FilePermissionDelete = "delete" // 移入回收站
+)
+
+// 文件授权对象类型
+const (
+	GrantSubjectUser = "user" // 用户，对象ID为用户ID
+	GrantSubjectTeam = "team" // 团队，对象ID为团队ID，授权对团队的有效成员生效
+)
+
+// FilePermissions 全部文件授权权限，按固定顺序排列
+var FilePermissions = []string{FilePermissionRead, FilePermissionWrite, FilePermissionShare, FilePermissionDelete}
+
+// FileGrant 文件和文件夹访问授权表结构
+//
+// 同一文件对同一用户或团队只有一条授权，权限以逗号分隔保存；文件夹上的授权对其下全部文件和子文件夹生效。
+// 文件所有者始终拥有全部权限，不需要授权
+type FileGrant struct {
+	basemodels.BaseModelWithoutSoftDelete
+	FileID      uint   `gorm:"not null;uniqueIndex:idx_file_grants_subject,priority:1" json:"file_id"`                                                                       // 文件或文件夹ID
+	SubjectType string `gorm:"type:enum('user','team');not null;uniqueIndex:idx_file_grants_subject,priority:2;index:idx_file_grants_lookup,priority:1" json:"subject_type"` // 授权对象类型
+	SubjectID   uint   `gorm:"not null;uniqueIndex:idx_file_grants_subject,priority:3;index:idx_file_grants_lookup,priority:2" json:"subject_id"`                            // 授权对象ID
+	Permissions string `gorm:"type:varchar(64);not null" json:"permissions"`                                                                                                 // 权限，逗号分隔，如 read,write
+	GrantedBy   uint   `gorm:"not null" json:"granted_by"`                                                                                                                   // 授权的管理员ID
+
+	// 关联关系
+	File File `gorm:"foreignKey:FileID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// TableName 文件授权表名
+func (FileGrant) TableName() string {
+	return "file_grants"
+}
+
+// PermissionList 返回授权的权限列表
+func (g *FileGrant) PermissionList() []string {
+	if g.Permissions == "" {
+		return []string{}
+	}
+	return strings.Split(g.Permissions, ",")
+}
+
+// HasPermission 检查授权是否包含指定权限
+func (g *FileGrant) HasPermission(permission string) bool {
+	for _, p := range g.PermissionList() {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidFilePermission 检查是否为有效的文件授权权限
+func IsValidFilePermission(permission string) bool {
+	for _, p := range FilePermissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package models
+
+import "encoding/json"
+
+// 病毒扫描状态
+const (
+	ScanStatusPending  = "pending"  // 等待扫描
+	ScanStatusClean    = "clean"    // 未检出病毒
+	ScanStatusInfected = "infected" // 检出病毒
+	ScanStatusFailed   = "failed"   // 扫描失败(clamd不可用、超时或超过大小限制)
+	ScanStatusSkipped  = "skipped"  // 未扫描(未启用病毒扫描、文件过大或上线前已存在的文件)
+)
+
+// 预览生成状态
+const (
+	PreviewStatusNone    = "none"    // 不生成预览(文件类型不支持或未启用预览)
+	PreviewStatusPending = "pending" // 等待生成
+	PreviewStatusReady   = "ready"   // 已生成
+	PreviewStatusFailed  = "failed"  // 源文件无法解码或渲染
+)
+
+// 搜索索引状态
+const (
+	IndexStatusPending = "pending" // 上传尚未完成，不会出现在搜索结果中
+	IndexStatusDone    = "done"    // 已可搜索
+)
+
+// FileProcessing 文件处理流水线状态
+//
+// 汇总病毒扫描、预览生成和搜索索引三个环节的状态，客户端据此展示"扫描中""预览生成中"等提示。
+// 搜索直接查询数据库的全文索引，上传完成(文件变为可用状态)即可搜索
+type FileProcessing struct {
+	Scan      string `json:"scan"`      // 病毒扫描状态
+	Preview   string `json:"preview"`   // 预览生成状态
+	Indexing  string `json:"indexing"`  // 搜索索引状态
+	Completed bool   `json:"completed"` // 是否全部环节都已结束(不再有等待中的环节)
+}
+
+// Processing 返回文件的处理流水线状态，文件夹没有处理流程，返回nil
+func (f *File) Processing() *FileProcessing {
+	if f.IsFolder {
+		return nil
+	}
+
+	processing := &FileProcessing{
+		Scan:     f.ScanStatus,
+		Preview:  f.PreviewStatus,
+		Indexing: IndexStatusPending,
+	}
+	if processing.Scan == "" {
+		processing.Scan = ScanStatusPending
+	}
+	if processing.Preview == "" {
+		processing.Preview = PreviewStatusNone
+	}
+	if f.IsActive() {
+		processing.Indexing = IndexStatusDone
+	}
+	processing.Completed = processing.Scan != ScanStatusPending &&
+		processing.Preview != PreviewStatusPending &&
+		processing.Indexing == IndexStatusDone
+	return processing
+}
+
+// fileJSON 去掉方法的File，避免MarshalJSON递归调用
+type fileJSON File
+
+// MarshalJSON 序列化文件，附加汇总的处理流水线状态
+func (f File) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		fileJSON
+		Processing *FileProcessing `json:"processing,omitempty"`
+	}{
+		fileJSON:   fileJSON(f),
+		Processing: f.Processing(),
+	})
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"database/sql"
+	"encoding/json"
 	"testing"
 	"time"
 
@@ -371,6 +372,39 @@ func TestFile_GetFullPath(t *testing.T) {
 	}
 }
 
+func TestFile_ArchiveState(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	at := func(hours int) *time.Time {
+		t := now.Add(time.Duration(hours) * time.Hour)
+		return &t
+	}
+
+	tests := []struct {
+		name     string
+		file     File
+		expected string
+		readable bool
+	}{
+		{"not archived", File{}, ArchiveStateAvailable, true},
+		{"archived", File{ArchivedAt: at(-100)}, ArchiveStateArchived, false},
+		{"restoring", File{ArchivedAt: at(-100), RestoreRequestedAt: at(-1)}, ArchiveStateRestoring, false},
+		{"restored", File{ArchivedAt: at(-100), RestoreRequestedAt: at(-5), RestoredUntil: at(24)}, ArchiveStateRestored, true},
+		{"restore expired", File{ArchivedAt: at(-100), RestoreRequestedAt: at(-50), RestoredUntil: at(-1)}, ArchiveStateArchived, false},
+		{"restoring again after expiry", File{ArchivedAt: at(-100), RestoreRequestedAt: at(-1), RestoredUntil: at(-2)}, ArchiveStateRestoring, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if state := tt.file.ArchiveState(now); state != tt.expected {
+				t.Errorf("ArchiveState() = %v, want %v", state, tt.expected)
+			}
+			if readable := tt.file.IsReadable(now); readable != tt.readable {
+				t.Errorf("IsReadable() = %v, want %v", readable, tt.readable)
+			}
+		})
+	}
+}
+
 func TestFileVersion_TableName(t *testing.T) {
 	version := &FileVersionTest{}
 	if version.TableName() != "file_versions" {
@@ -481,6 +515,102 @@ func TestFileShare_IsAccessible(t *testing.T) {
 	}
 }
 
+func TestFileShare_BeforeSaveHashesPassword(t *testing.T) {
+	plain := "secret"
+	share := &FileShare{Password: &plain}
+	if err := share.BeforeSave(nil); err != nil {
+		t.Fatalf("BeforeSave() error = %v", err)
+	}
+	if !share.HasPassword || share.Password == nil || *share.Password == "secret" {
+		t.Fatal("password should be stored as a bcrypt hash")
+	}
+
+	// 已哈希的密码再次保存保持不变
+	hashed := *share.Password
+	if err := share.BeforeSave(nil); err != nil {
+		t.Fatalf("BeforeSave() error = %v", err)
+	}
+	if *share.Password != hashed {
+		t.Error("hashed password should not be rehashed")
+	}
+
+	// 空密码表示取消密码
+	empty := ""
+	share.Password = &empty
+	if err := share.BeforeSave(nil); err != nil {
+		t.Fatalf("BeforeSave() error = %v", err)
+	}
+	if share.HasPassword || share.Password != nil {
+		t.Error("empty password should clear password protection")
+	}
+}
+
+func TestFileShare_CheckPassword(t *testing.T) {
+	plain := "secret"
+	share := &FileShare{Password: &plain}
+	if err := share.BeforeSave(nil); err != nil {
+		t.Fatalf("BeforeSave() error = %v", err)
+	}
+
+	if ok, rehash := share.CheckPassword("secret"); !ok || rehash {
+		t.Errorf("CheckPassword(correct) = %v, %v, want true, false", ok, rehash)
+	}
+	if ok, _ := share.CheckPassword("wrong"); ok {
+		t.Error("CheckPassword(wrong) should fail")
+	}
+
+	// 历史明文密码匹配时要求重新哈希
+	legacy := "legacy"
+	legacyShare := &FileShare{Password: &legacy, HasPassword: true}
+	if ok, rehash := legacyShare.CheckPassword("legacy"); !ok || !rehash {
+		t.Errorf("CheckPassword(legacy) = %v, %v, want true, true", ok, rehash)
+	}
+	if ok, _ := legacyShare.CheckPassword("other"); ok {
+		t.Error("CheckPassword(legacy wrong) should fail")
+	}
+
+	if ok, _ := (&FileShare{}).CheckPassword(""); !ok {
+		t.Error("share without password should always pass")
+	}
+}
+
+func TestFileShare_IsPasswordLocked(t *testing.T) {
+	now := time.Now()
+	until := now.Add(time.Minute)
+	share := &FileShare{PasswordLockedUntil: &until}
+	if !share.IsPasswordLocked(now) {
+		t.Error("share should be locked before PasswordLockedUntil")
+	}
+	if share.IsPasswordLocked(until) {
+		t.Error("share should be unlocked at PasswordLockedUntil")
+	}
+	if (&FileShare{}).IsPasswordLocked(now) {
+		t.Error("share without lock should not be locked")
+	}
+}
+
+func TestFileShare_CurrentTransferUsed(t *testing.T) {
+	now := time.Date(2024, 3, 15, 8, 0, 0, 0, time.UTC)
+	periodStart := TransferPeriodStart(now)
+	if !periodStart.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("TransferPeriodStart() = %v", periodStart)
+	}
+
+	current := &FileShare{TransferUsed: 100, TransferPeriodStart: &periodStart}
+	if current.CurrentTransferUsed(now) != 100 {
+		t.Error("usage in current period should be counted")
+	}
+
+	lastMonth := periodStart.AddDate(0, -1, 0)
+	stale := &FileShare{TransferUsed: 100, TransferPeriodStart: &lastMonth}
+	if stale.CurrentTransferUsed(now) != 0 {
+		t.Error("usage from previous period should be ignored")
+	}
+	if (&FileShare{TransferUsed: 100}).CurrentTransferUsed(now) != 0 {
+		t.Error("usage without period should be ignored")
+	}
+}
+
 func TestFileTag_TableName(t *testing.T) {
 	tag := &FileTagTest{}
 	if tag.TableName() != "file_tags" {
@@ -667,3 +797,114 @@ func TestCreateFileWithDatabase(t *testing.T) {
 		t.Error("Retrieved file should be active")
 	}
 }
+
+func TestFile_BeforeSaveNormalizesName(t *testing.T) {
+	db, err := setupFileTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	dryRun := db.Session(&gorm.Session{DryRun: true})
+
+	// 创建时规范化名称
+	file := &File{UserID: 1, Name: "  报告.pdf "}
+	if err := dryRun.Create(file).Error; err != nil {
+		t.Fatalf("Create should succeed: %v", err)
+	}
+	if file.Name != "报告.pdf" {
+		t.Errorf("Name = %q, want %q", file.Name, "报告.pdf")
+	}
+
+	// 不安全的名称在任何写入路径都被拒绝
+	if err := dryRun.Create(&File{UserID: 1, Name: "../secret"}).Error; err == nil {
+		t.Error("Create with path traversal should fail")
+	}
+	if err := dryRun.Model(&File{}).Where("id = ?", 1).Updates(map[string]interface{}{"name": "NUL"}).Error; err == nil {
+		t.Error("Rename to reserved name should fail")
+	}
+	if err := dryRun.Model(&File{}).Where("id = ?", 1).Updates(&File{Name: "a\x00b"}).Error; err == nil {
+		t.Error("Rename with control character should fail")
+	}
+
+	// 不涉及名称的更新不做校验
+	if err := dryRun.Model(&File{}).Where("id = ?", 1).Updates(map[string]interface{}{"status": "active"}).Error; err != nil {
+		t.Errorf("Update without name should succeed: %v", err)
+	}
+	if err := dryRun.Model(&File{}).Where("id = ?", 1).Updates(&File{Status: "active"}).Error; err != nil {
+		t.Errorf("Struct update without name should succeed: %v", err)
+	}
+
+	// 重命名时写入规范化后的名称
+	stmt := dryRun.Model(&File{}).Where("id = ?", 1).Updates(map[string]interface{}{"name": " 新名称 "}).Statement
+	if stmt.Error != nil {
+		t.Fatalf("Rename should succeed: %v", stmt.Error)
+	}
+	found := false
+	for _, v := range stmt.Vars {
+		if v == "新名称" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Rename should write normalized name, vars = %v", stmt.Vars)
+	}
+}
+
+func TestFile_Processing(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     File
+		expected FileProcessing
+	}{
+		{"uploading", File{Status: "uploading"}, FileProcessing{ScanStatusPending, PreviewStatusNone, IndexStatusPending, false}},
+		{"scanning", File{Status: "active", ScanStatus: ScanStatusPending, PreviewStatus: PreviewStatusReady}, FileProcessing{ScanStatusPending, PreviewStatusReady, IndexStatusDone, false}},
+		{"preview pending", File{Status: "active", ScanStatus: ScanStatusClean, PreviewStatus: PreviewStatusPending}, FileProcessing{ScanStatusClean, PreviewStatusPending, IndexStatusDone, false}},
+		{"completed", File{Status: "active", ScanStatus: ScanStatusInfected, PreviewStatus: PreviewStatusFailed}, FileProcessing{ScanStatusInfected, PreviewStatusFailed, IndexStatusDone, true}},
+		{"no preview", File{Status: "active", ScanStatus: ScanStatusSkipped}, FileProcessing{ScanStatusSkipped, PreviewStatusNone, IndexStatusDone, true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.file.Processing(); got == nil || *got != tt.expected {
+				t.Errorf("Processing() = %+v, want %+v", got, tt.expected)
+			}
+		})
+	}
+
+	folder := File{IsFolder: true, Status: "active"}
+	if folder.Processing() != nil {
+		t.Error("Folder should have no processing status")
+	}
+}
+
+func TestFile_MarshalJSONIncludesProcessing(t *testing.T) {
+	data, err := json.Marshal(&File{Name: "a.pdf", Status: "active", ScanStatus: ScanStatusClean})
+	if err != nil {
+		t.Fatalf("Marshal should succeed: %v", err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal should succeed: %v", err)
+	}
+	if decoded["name"] != "a.pdf" {
+		t.Errorf("name = %v, want a.pdf", decoded["name"])
+	}
+	processing, ok := decoded["processing"].(map[string]interface{})
+	if !ok || processing["scan"] != ScanStatusClean || processing["completed"] != true {
+		t.Errorf("processing = %v", decoded["processing"])
+	}
+	if _, ok := decoded["scan_status"]; ok {
+		t.Error("Raw scan status column should not be serialized")
+	}
+
+	data, err = json.Marshal(File{Name: "docs", IsFolder: true})
+	if err != nil {
+		t.Fatalf("Marshal should succeed: %v", err)
+	}
+	var folder map[string]interface{}
+	if err := json.Unmarshal(data, &folder); err != nil {
+		t.Fatalf("Unmarshal should succeed: %v", err)
+	}
+	if _, ok := folder["processing"]; ok {
+		t.Error("Folder should not include processing")
+	}
+}
@@ -0,0 +1,118 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	basemodels "cloudpan/internal/pkg/database/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrIdentifierInUse 标识符已被使用(包括软删除的记录和已物理删除的墓碑)
+var ErrIdentifierInUse = errors.New("identifier already in use")
+
+// maxIdentifierAttempts 生成唯一标识符的最大尝试次数
+const maxIdentifierAttempts = 5
+
+// 标识符墓碑类型常量
+const (
+	TombstoneKindFileUUID  = "file_uuid"  // 文件UUID
+	TombstoneKindShareCode = "share_code" // 分享码
+)
+
+// IdentifierTombstone 标识符墓碑表结构
+//
+// 记录已被物理删除的实体曾经使用过的对外标识符(UUID、分享码等)，
+// 保证这些标识符永远不会被重新分配，避免旧链接指向新内容
+type IdentifierTombstone struct {
+	basemodels.BaseModelWithoutSoftDelete
+	Kind        string    `gorm:"type:varchar(50);not null;uniqueIndex:uk_identifier_tombstones_kind_value" json:"kind"`   // 标识符类型
+	Value       string    `gorm:"type:varchar(255);not null;uniqueIndex:uk_identifier_tombstones_kind_value" json:"value"` // 标识符值
+	SourceTable string    `gorm:"type:varchar(100);not null" json:"source_table"`                                          // 来源表
+	SourceID    uint      `gorm:"not null" json:"source_id"`                                                               // 来源记录ID
+	RetiredAt   time.Time `gorm:"not null" json:"retired_at"`                                                              // 退役时间
+}
+
+// TableName 标识符墓碑表名
+func (IdentifierTombstone) TableName() string {
+	return "identifier_tombstones"
+}
+
+// IsIdentifierTaken 检查标识符是否已被占用
+//
+// 同时检查来源表中的全部记录(包括软删除的记录)和墓碑表
+func IsIdentifierTaken(tx *gorm.DB, kind string, model interface{}, column, value string) (bool, error) {
+	db := tx.Session(&gorm.Session{NewDB: true})
+
+	var count int64
+	if err := db.Unscoped().Model(model).Where(column+" = ?", value).Count(&count).Error; err != nil {
+		return false, fmt.Errorf("检查标识符失败: %w", err)
+	}
+	if count > 0 {
+		return true, nil
+	}
+
+	if err := db.Model(&IdentifierTombstone{}).Where("kind = ? AND value = ?", kind, value).Count(&count).Error; err != nil {
+		return false, fmt.Errorf("检查标识符墓碑失败: %w", err)
+	}
+	return count > 0, nil
+}
+
+// RetireIdentifier 将物理删除的记录的标识符写入墓碑表(重复写入会被忽略)
+func RetireIdentifier(tx *gorm.DB, kind, value, sourceTable string, sourceID uint) error {
+	if value == "" {
+		return nil
+	}
+
+	tombstone := &IdentifierTombstone{
+		Kind:        kind,
+		Value:       value,
+		SourceTable: sourceTable,
+		SourceID:    sourceID,
+		RetiredAt:   time.Now(),
+	}
+
+	db := tx.Session(&gorm.Session{NewDB: true})
+	var count int64
+	if err := db.Model(&IdentifierTombstone{}).Where("kind = ? AND value = ?", kind, value).Count(&count).Error; err != nil {
+		return fmt.Errorf("检查标识符墓碑失败: %w", err)
+	}
+	if count > 0 {
+		return nil
+	}
+	return db.Create(tombstone).Error
+}
+
+// ensureUniqueIdentifier 为新记录分配全局唯一的标识符
+//
+// current不为空时只校验是否可用；为空时调用generate生成并在冲突时重试
+func ensureUniqueIdentifier(tx *gorm.DB, kind string, model interface{}, column, current string, generate func() string) (string, error) {
+	if tx == nil || tx.Statement == nil {
+		if current == "" {
+			return generate(), nil
+		}
+		return current, nil
+	}
+
+	for attempt := 0; attempt < maxIdentifierAttempts; attempt++ {
+		value := current
+		if value == "" {
+			value = generate()
+		}
+
+		taken, err := IsIdentifierTaken(tx, kind, model, column, value)
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return value, nil
+		}
+		if current != "" {
+			return "", fmt.Errorf("%s %s: %w", kind, value, ErrIdentifierInUse)
+		}
+	}
+
+	return "", fmt.Errorf("%s 连续 %d 次生成冲突: %w", kind, maxIdentifierAttempts, ErrIdentifierInUse)
+}
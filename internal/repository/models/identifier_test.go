@@ -0,0 +1,93 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	_ "modernc.org/sqlite" // 使用纯Go的SQLite驱动
+
+	basemodels "cloudpan/internal/pkg/database/models"
+)
+
+// identifierTestModel 标识符测试模型
+type identifierTestModel struct {
+	basemodels.BaseModel
+	Code string `gorm:"type:varchar(50);uniqueIndex"`
+}
+
+func setupIdentifierTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	sqlDB, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open sqlite: %v", err)
+	}
+	db, err := gorm.Open(sqlite.Dialector{Conn: sqlDB}, &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open gorm: %v", err)
+	}
+	if err := db.AutoMigrate(&identifierTestModel{}, &IdentifierTombstone{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	return db
+}
+
+func TestEnsureUniqueIdentifier_SkipsSoftDeletedAndRetired(t *testing.T) {
+	db := setupIdentifierTestDB(t)
+
+	// 软删除的记录仍然占用标识符
+	deleted := &identifierTestModel{Code: "soft"}
+	if err := db.Create(deleted).Error; err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if err := db.Delete(deleted).Error; err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	// 墓碑中的标识符不可复用
+	if err := RetireIdentifier(db, "test", "retired", "identifier_test_models", 99); err != nil {
+		t.Fatalf("RetireIdentifier failed: %v", err)
+	}
+	// 重复退役不报错
+	if err := RetireIdentifier(db, "test", "retired", "identifier_test_models", 99); err != nil {
+		t.Fatalf("RetireIdentifier should be idempotent: %v", err)
+	}
+
+	candidates := []string{"soft", "retired", "fresh"}
+	next := 0
+	generate := func() string {
+		value := candidates[next]
+		next++
+		return value
+	}
+
+	code, err := ensureUniqueIdentifier(db.Session(&gorm.Session{}), "test", &identifierTestModel{}, "code", "", generate)
+	if err != nil {
+		t.Fatalf("ensureUniqueIdentifier failed: %v", err)
+	}
+	if code != "fresh" {
+		t.Errorf("Expected fresh, got %s", code)
+	}
+}
+
+func TestEnsureUniqueIdentifier_ExplicitValueInUse(t *testing.T) {
+	db := setupIdentifierTestDB(t)
+
+	if err := RetireIdentifier(db, "test", "taken", "identifier_test_models", 1); err != nil {
+		t.Fatalf("RetireIdentifier failed: %v", err)
+	}
+
+	_, err := ensureUniqueIdentifier(db.Session(&gorm.Session{}), "test", &identifierTestModel{}, "code", "taken", func() string { return "unused" })
+	if !errors.Is(err, ErrIdentifierInUse) {
+		t.Errorf("Expected ErrIdentifierInUse, got %v", err)
+	}
+}
+
+func TestEnsureUniqueIdentifier_WithoutTransaction(t *testing.T) {
+	code, err := ensureUniqueIdentifier(nil, "test", &identifierTestModel{}, "code", "", func() string { return "generated" })
+	if err != nil || code != "generated" {
+		t.Errorf("Expected generated value without db, got %s, %v", code, err)
+	}
+}
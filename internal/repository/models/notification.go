@@ -92,6 +92,8 @@ const (
 	NotificationTypeMessageReply      = "message_reply"      // 消息回复
 	NotificationTypeStorageWarning    = "storage_warning"    // 存储空间警告
 	NotificationTypeSecurityAlert     = "security_alert"     // 安全警告
+	NotificationTypeShareAbuse        = "share_abuse"        // 分享被举报(管理员审核提醒、分享者暂停和处理结果通知)
+	NotificationTypeContentModeration = "content_moderation" // 分享图片内容审核(管理员复核提醒、分享被禁止的通知)
 	NotificationTypeSystemUpdate      = "system_update"      // 系统更新
 	NotificationTypePasswordChanged   = "password_changed"   // 密码修改
 	NotificationTypeLoginAlert        = "login_alert"        // 登录警告
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+
+	basemodels "cloudpan/internal/pkg/database/models"
+)
+
+// SCIMToken 目录同步(SCIM)访问令牌表结构
+//
+// 每个身份提供方最多一个令牌，重新签发时覆盖旧令牌；只保存令牌的SHA-256哈希
+type SCIMToken struct {
+	basemodels.BaseModelWithoutSoftDelete
+	ProviderID  uint       `gorm:"not null;uniqueIndex" json:"provider_id"`       // 身份提供方ID
+	TokenHash   string     `gorm:"type:char(64);not null;uniqueIndex" json:"-"`   // 令牌SHA-256(十六进制)
+	TokenPrefix string     `gorm:"type:varchar(16);not null" json:"token_prefix"` // 令牌前缀，用于识别
+	CreatedBy   uint       `gorm:"not null" json:"created_by"`                    // 签发令牌的管理员ID，同步创建的团队归其所有
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`                        // 最近使用时间
+}
+
+// TableName 目录同步令牌表名
+func (SCIMToken) TableName() string {
+	return "scim_tokens"
+}
+
+// SCIMUser 目录同步的用户关联表结构
+//
+// 身份提供方通过SCIM开通或关联的本地账户，SCIM资源ID使用用户UUID
+type SCIMUser struct {
+	basemodels.BaseModelWithoutSoftDelete
+	ProviderID uint    `gorm:"not null;uniqueIndex:idx_scim_users_provider_user;uniqueIndex:idx_scim_users_provider_user_name" json:"provider_id"` // 身份提供方ID
+	UserID     uint    `gorm:"not null;uniqueIndex:idx_scim_users_provider_user;index" json:"user_id"`                                             // 本地用户ID
+	UserName   string  `gorm:"type:varchar(255);not null;uniqueIndex:idx_scim_users_provider_user_name" json:"user_name"`                          // 身份提供方的userName(小写)
+	ExternalID *string `gorm:"type:varchar(255);index" json:"external_id,omitempty"`                                                               // 身份提供方的externalId
+
+	// 关联关系
+	User User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+// TableName 目录同步用户表名
+func (SCIMUser) TableName() string {
+	return "scim_users"
+}
+
+// SCIMGroup 目录同步的组表结构
+//
+// 每个组对应一个团队，组成员同步为团队成员；SCIM资源ID使用团队UUID
+type SCIMGroup struct {
+	basemodels.BaseModelWithoutSoftDelete
+	ProviderID  uint    `gorm:"not null;uniqueIndex:idx_scim_groups_provider_name" json:"provider_id"`                    // 身份提供方ID
+	TeamID      uint    `gorm:"not null;uniqueIndex" json:"team_id"`                                                      // 团队ID
+	DisplayName string  `gorm:"type:varchar(255);not null;uniqueIndex:idx_scim_groups_provider_name" json:"display_name"` // 组名称
+	ExternalID  *string `gorm:"type:varchar(255)" json:"external_id,omitempty"`                                           // 身份提供方的externalId
+
+	// 关联关系
+	Team Team `gorm:"foreignKey:TeamID" json:"team,omitempty"`
+}
+
+// TableName 目录同步组表名
+func (SCIMGroup) TableName() string {
+	return "scim_groups"
+}
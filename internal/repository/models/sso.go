@@ -0,0 +1,128 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	basemodels "cloudpan/internal/pkg/database/models"
+)
+
+// SSO登录后可映射的角色，按权限从低到高排列；superuser不能通过身份提供方授予
+var SSORoles = []string{"user", "moderator", "admin"}
+
+// SSOProvider 企业单点登录身份提供方(OIDC)表结构
+//
+// 每个租户配置一个身份提供方。通过验证的邮箱域名(SSODomain)登录时跳转到该身份提供方，
+// 首次登录的用户即时开通账户(JITEnabled)，ID令牌中的组按 RoleMappings 映射为角色。
+// 布尔字段没有gorm默认值，创建时总是写入(避免false被数据库默认值覆盖)
+type SSOProvider struct {
+	basemodels.BaseModel
+	// 基本信息
+	Tenant string `gorm:"type:varchar(100);not null;uniqueIndex" json:"tenant"` // 租户标识，写入开通用户的profile.tenant，创建后不可修改
+	Name   string `gorm:"type:varchar(100);not null" json:"name"`               // 显示名称(登录按钮文字)
+
+	// OIDC客户端参数
+	Issuer       string `gorm:"type:varchar(500);not null" json:"issuer"`                        // 签发者地址，用于发现文档
+	ClientID     string `gorm:"type:varchar(255);not null" json:"client_id"`                     // 客户端ID
+	ClientSecret string `gorm:"type:varchar(1024);serializer:encrypted" json:"-"`                // 客户端密钥(加密存储)，为空时作为公共客户端
+	Scopes       string `gorm:"type:varchar(255);not null;default:''" json:"scopes"`             // 额外申请的scope(空格分隔)，openid总是包含
+	GroupsClaim  string `gorm:"type:varchar(100);not null;default:'groups'" json:"groups_claim"` // ID令牌中组列表的声明名
+
+	// 账户和角色
+	RoleMappings *basemodels.JSONMap `gorm:"type:json" json:"role_mappings,omitempty"`                     // 组到角色的映射，多个组匹配时取最高角色
+	DefaultRole  string              `gorm:"type:varchar(20);not null;default:'user'" json:"default_role"` // 没有组匹配时的角色
+	JITEnabled   bool                `gorm:"not null" json:"jit_enabled"`                                  // 是否为首次登录的用户即时开通账户
+	EnforceSSO   bool                `gorm:"not null" json:"enforce_sso"`                                  // 是否禁止已验证域名的用户使用密码登录
+	IsActive     bool                `gorm:"not null" json:"is_active"`                                    // 是否启用
+
+	// 关联关系
+	Domains []SSODomain `gorm:"foreignKey:ProviderID" json:"domains,omitempty"`
+}
+
+// TableName 身份提供方表名
+func (SSOProvider) TableName() string {
+	return "sso_providers"
+}
+
+// EncryptionSubject 敏感字段加密主体，每个租户的客户端密钥使用独立派生的密钥
+func (p *SSOProvider) EncryptionSubject() string {
+	return p.Tenant
+}
+
+// ScopeList 返回额外申请的scope
+func (p *SSOProvider) ScopeList() []string {
+	return strings.Fields(p.Scopes)
+}
+
+// RoleForGroups 按组映射角色，多个组匹配时取权限最高的角色，都不匹配时返回默认角色
+func (p *SSOProvider) RoleForGroups(groups []string) string {
+	role := p.DefaultRole
+	if roleLevel(role) == 0 {
+		role = SSORoles[0]
+	}
+	if p.RoleMappings == nil {
+		return role
+	}
+	for _, group := range groups {
+		mapped, _ := (*p.RoleMappings)[group].(string)
+		if roleLevel(mapped) > roleLevel(role) {
+			role = mapped
+		}
+	}
+	return role
+}
+
+// IsSSORole 检查角色是否可以通过身份提供方授予
+func IsSSORole(role string) bool {
+	return roleLevel(role) > 0
+}
+
+// roleLevel 返回角色在 SSORoles 中的级别，不可授予的角色为0
+func roleLevel(role string) int {
+	for i, r := range SSORoles {
+		if r == role {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// SSODomain 身份提供方认领的邮箱域名表结构
+//
+// 租户在域名的DNS中添加 VerificationToken 对应的TXT记录后完成验证，
+// 只有验证过的域名才会按邮箱跳转到身份提供方、即时开通账户和强制SSO登录
+type SSODomain struct {
+	basemodels.BaseModelWithoutSoftDelete
+	ProviderID        uint       `gorm:"not null;index" json:"provider_id"`                    // 身份提供方ID
+	Domain            string     `gorm:"type:varchar(255);not null;uniqueIndex" json:"domain"` // 邮箱域名(小写)
+	VerificationToken string     `gorm:"type:varchar(100);not null" json:"verification_token"` // DNS TXT记录中的验证值
+	VerifiedAt        *time.Time `json:"verified_at,omitempty"`                                // 验证时间，为空表示未验证
+}
+
+// TableName 邮箱域名表名
+func (SSODomain) TableName() string {
+	return "sso_domains"
+}
+
+// IsVerified 检查域名是否已验证
+func (d *SSODomain) IsVerified() bool {
+	return d.VerifiedAt != nil
+}
+
+// SSOIdentity 身份提供方用户与本地账户的关联表结构
+//
+// 以身份提供方的subject识别用户，邮箱变更后仍关联到同一个账户
+type SSOIdentity struct {
+	basemodels.BaseModelWithoutSoftDelete
+	ProviderID  uint       `gorm:"not null;uniqueIndex:idx_sso_identities_provider_subject" json:"provider_id"`               // 身份提供方ID
+	Subject     string     `gorm:"type:varchar(255);not null;uniqueIndex:idx_sso_identities_provider_subject" json:"subject"` // 身份提供方的用户标识(sub)
+	UserID      uint       `gorm:"not null;index" json:"user_id"`                                                             // 本地用户ID
+	Email       string     `gorm:"type:varchar(255)" json:"email"`                                                            // 最近一次登录时的邮箱
+	Role        string     `gorm:"type:varchar(20);not null;default:'user'" json:"role"`                                      // 最近一次登录时映射的角色
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`                                                                   // 最近登录时间
+}
+
+// TableName 单点登录身份关联表名
+func (SSOIdentity) TableName() string {
+	return "sso_identities"
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"errors"
 	"time"
 
 	basemodels "cloudpan/internal/pkg/database/models"
@@ -21,9 +22,9 @@ type RecycleBin struct {
 	OriginalPath     string `gorm:"type:varchar(2000);not null" json:"original_path"` // 原始路径
 	OriginalParentID *uint  `json:"original_parent_id,omitempty"`                     // 原始父文件夹ID
 
-	// 删除信息
+	// 删除信息(删除时间不能命名为DeletedAt，否则会覆盖BaseModel的软删除字段)
 	DeletedBy    uint      `gorm:"not null" json:"deleted_by"`                       // 删除者ID
-	DeletedAt    time.Time `gorm:"not null" json:"deleted_at"`                       // 删除时间
+	TrashedAt    time.Time `gorm:"not null;index" json:"trashed_at"`                 // 移入回收站的时间
 	DeleteReason *string   `gorm:"type:varchar(255)" json:"delete_reason,omitempty"` // 删除原因
 
 	// 文件信息
@@ -61,13 +62,13 @@ func (r *RecycleBin) BeforeCreate(tx *gorm.DB) error {
 		r.UUID = basemodels.GenerateUUID()
 	}
 
-	if r.DeletedAt.IsZero() {
-		r.DeletedAt = time.Now()
+	if r.TrashedAt.IsZero() {
+		r.TrashedAt = time.Now()
 	}
 
 	if r.AutoDeleteAt.IsZero() {
 		// 默认30天后自动删除
-		r.AutoDeleteAt = r.DeletedAt.Add(30 * 24 * time.Hour)
+		r.AutoDeleteAt = r.TrashedAt.Add(30 * 24 * time.Hour)
 	}
 
 	return r.BaseModel.BeforeCreate(tx)
@@ -92,50 +93,57 @@ func (r *RecycleBin) Restore(userID uint, restorePath string) {
 	r.RestorePath = &restorePath
 }
 
-// AuditLog 审计日志表结构
+// 安全审计结果
+const (
+	AuditResultSuccess = "success" // 成功
+	AuditResultFailure = "failure" // 失败(如密码错误)
+)
+
+// 安全审计严重程度
+const (
+	AuditSeverityLow      = "low"      // 常规操作
+	AuditSeverityMedium   = "medium"   // 需要关注的操作
+	AuditSeverityHigh     = "high"     // 高风险操作
+	AuditSeverityCritical = "critical" // 严重操作
+)
+
+// ErrAuditLogImmutable 安全审计日志写入后不允许修改或删除
+var ErrAuditLogImmutable = errors.New("audit log is immutable")
+
+// AuditLog 安全审计日志表结构
+//
+// 记录与账户安全相关的操作(登录、修改和重置密码、创建分享、权限变更、删除等)，
+// 包括操作用户、IP、User-Agent和操作前后的快照。日志只允许追加，模型钩子拒绝更新和删除
 type AuditLog struct {
-	basemodels.BaseModel
-	// 基本信息
-	UUID   string `gorm:"type:char(36);uniqueIndex;not null" json:"uuid"` // 日志唯一标识符
-	UserID *uint  `gorm:"index" json:"user_id,omitempty"`                 // 用户ID(系统操作可为空)
+	ID   uint   `gorm:"primarykey" json:"id"`
+	UUID string `gorm:"type:char(36);uniqueIndex;not null" json:"uuid"` // 日志唯一标识符
 
 	// 操作信息
-	Action       string  `gorm:"type:varchar(100);not null;index" json:"action"`        // 操作类型
-	Module       string  `gorm:"type:varchar(100);not null;index" json:"module"`        // 模块名称
-	ResourceType string  `gorm:"type:varchar(100);not null;index" json:"resource_type"` // 资源类型
-	ResourceID   *string `gorm:"type:varchar(100);index" json:"resource_id,omitempty"`  // 资源ID
-	ResourceName *string `gorm:"type:varchar(255)" json:"resource_name,omitempty"`      // 资源名称
+	UserID       *uint   `gorm:"index" json:"user_id,omitempty"`                                                         // 操作用户ID(未识别用户的登录失败为空)
+	Action       string  `gorm:"type:varchar(100);not null;index" json:"action"`                                         // 操作类型
+	Category     string  `gorm:"type:varchar(50);index" json:"category"`                                                 // 操作分类，为操作类型的前缀
+	ResourceType string  `gorm:"type:varchar(50);not null;index" json:"resource_type"`                                   // 操作对象类型
+	ResourceID   *string `gorm:"type:varchar(100);index" json:"resource_id,omitempty"`                                   // 操作对象ID
+	ResourceName *string `gorm:"type:varchar(255)" json:"resource_name,omitempty"`                                       // 操作对象名称
+	Result       string  `gorm:"type:enum('success','failure','error','warning');default:'success';index" json:"result"` // 操作结果
+	Severity     string  `gorm:"type:enum('low','medium','high','critical');default:'low';index" json:"severity"`        // 严重程度
+
+	// 变更内容
+	Before  *basemodels.JSONMap `gorm:"type:json" json:"before,omitempty"`  // 操作前的快照
+	After   *basemodels.JSONMap `gorm:"type:json" json:"after,omitempty"`   // 操作后的快照
+	Details *basemodels.JSONMap `gorm:"type:json" json:"details,omitempty"` // 附加信息(如失败原因)
 
 	// 请求信息
-	Method    string  `gorm:"type:varchar(20);not null" json:"method"`           // HTTP方法
-	URL       string  `gorm:"type:varchar(2000);not null" json:"url"`            // 请求URL
-	UserAgent *string `gorm:"type:varchar(1000)" json:"user_agent,omitempty"`    // 用户代理
-	IPAddress string  `gorm:"type:varchar(45);not null;index" json:"ip_address"` // IP地址
-	Location  *string `gorm:"type:varchar(200)" json:"location,omitempty"`       // 地理位置
-
-	// 结果信息
-	Status       string  `gorm:"type:enum('success','failed','error','warning');default:'success'" json:"status"` // 操作状态
-	StatusCode   int     `gorm:"default:200" json:"status_code"`                                                  // HTTP状态码
-	ErrorMessage *string `gorm:"type:text" json:"error_message,omitempty"`                                        // 错误信息
-
-	// 数据信息
-	RequestData  *basemodels.JSONMap `gorm:"type:json" json:"request_data,omitempty"`  // 请求数据
-	ResponseData *basemodels.JSONMap `gorm:"type:json" json:"response_data,omitempty"` // 响应数据
-	Changes      *basemodels.JSONMap `gorm:"type:json" json:"changes,omitempty"`       // 数据变更
+	IPAddress     string  `gorm:"type:varchar(45);index" json:"ip_address"`         // IP地址
+	UserAgent     *string `gorm:"type:text" json:"user_agent,omitempty"`            // 用户代理
+	RequestID     *string `gorm:"type:varchar(100)" json:"request_id,omitempty"`    // 请求ID，用于关联访问日志
+	RequestMethod *string `gorm:"type:varchar(10)" json:"request_method,omitempty"` // 请求方法
+	RequestURI    *string `gorm:"type:varchar(1000)" json:"request_uri,omitempty"`  // 请求URI
 
-	// 时间信息
-	Duration  int64     `gorm:"default:0" json:"duration"`        // 执行时长(毫秒)
-	CreatedAt time.Time `gorm:"not null;index" json:"created_at"` // 创建时间
-
-	// 风险评估
-	RiskLevel   string `gorm:"type:enum('low','medium','high','critical');default:'low'" json:"risk_level"` // 风险级别
-	IsAnonymous bool   `gorm:"default:false" json:"is_anonymous"`                                           // 是否匿名操作
-
-	// 关联关系
-	User *User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	CreatedAt time.Time `gorm:"not null;index" json:"created_at"` // 操作时间
 }
 
-// TableName 审计日志表名
+// TableName 安全审计日志表名
 func (AuditLog) TableName() string {
 	return "audit_logs"
 }
@@ -145,22 +153,82 @@ func (a *AuditLog) BeforeCreate(tx *gorm.DB) error {
 	if a.UUID == "" {
 		a.UUID = basemodels.GenerateUUID()
 	}
-
 	if a.CreatedAt.IsZero() {
 		a.CreatedAt = time.Now()
 	}
+	return nil
+}
 
-	return a.BaseModel.BeforeCreate(tx)
+// BeforeUpdate 审计日志不允许修改
+func (a *AuditLog) BeforeUpdate(tx *gorm.DB) error {
+	return ErrAuditLogImmutable
+}
+
+// BeforeDelete 审计日志不允许删除
+func (a *AuditLog) BeforeDelete(tx *gorm.DB) error {
+	return ErrAuditLogImmutable
 }
 
 // IsSuccessful 检查操作是否成功
 func (a *AuditLog) IsSuccessful() bool {
-	return a.Status == "success"
+	return a.Result == AuditResultSuccess
 }
 
 // IsHighRisk 检查是否为高风险操作
 func (a *AuditLog) IsHighRisk() bool {
-	return a.RiskLevel == "high" || a.RiskLevel == "critical"
+	return a.Severity == AuditSeverityHigh || a.Severity == AuditSeverityCritical
+}
+
+// ErrAdminAuditLogImmutable 管理员审计日志写入后不允许修改或删除
+var ErrAdminAuditLogImmutable = errors.New("admin audit log is immutable")
+
+// AdminAuditLog 管理员操作审计日志表结构
+//
+// 记录管理员的特权操作(停用用户、调整配额、模拟登录、修改特性开关等)，用于合规审查。
+// 日志只允许追加：模型钩子拒绝更新和删除，数据库触发器同样拒绝直接修改
+type AdminAuditLog struct {
+	ID uint `gorm:"primarykey" json:"id"`
+
+	// 操作信息
+	ActorID    uint   `gorm:"not null;index" json:"actor_id"`                                                 // 操作的管理员ID
+	Action     string `gorm:"type:varchar(100);not null;index" json:"action"`                                 // 操作类型
+	TargetType string `gorm:"type:varchar(50);not null;index:idx_admin_audit_logs_target" json:"target_type"` // 操作对象类型
+	TargetID   string `gorm:"type:varchar(100);not null;index:idx_admin_audit_logs_target" json:"target_id"`  // 操作对象ID
+	Reason     string `gorm:"type:varchar(500)" json:"reason"`                                                // 操作原因
+
+	// 变更内容
+	Before *basemodels.JSONMap `gorm:"type:json" json:"before,omitempty"` // 操作前的值
+	After  *basemodels.JSONMap `gorm:"type:json" json:"after,omitempty"`  // 操作后的值
+
+	// 请求信息
+	IPAddress string `gorm:"type:varchar(45)" json:"ip_address"`   // IP地址
+	UserAgent string `gorm:"type:varchar(1000)" json:"user_agent"` // 用户代理
+	RequestID string `gorm:"type:varchar(64)" json:"request_id"`   // 请求ID，用于关联访问日志
+
+	CreatedAt time.Time `gorm:"not null;index" json:"created_at"` // 操作时间
+}
+
+// TableName 管理员审计日志表名
+func (AdminAuditLog) TableName() string {
+	return "admin_audit_logs"
+}
+
+// BeforeCreate 创建前钩子
+func (a *AdminAuditLog) BeforeCreate(tx *gorm.DB) error {
+	if a.CreatedAt.IsZero() {
+		a.CreatedAt = time.Now()
+	}
+	return nil
+}
+
+// BeforeUpdate 审计日志不允许修改
+func (a *AdminAuditLog) BeforeUpdate(tx *gorm.DB) error {
+	return ErrAdminAuditLogImmutable
+}
+
+// BeforeDelete 审计日志不允许删除
+func (a *AdminAuditLog) BeforeDelete(tx *gorm.DB) error {
+	return ErrAdminAuditLogImmutable
 }
 
 // SystemSetting 系统设置表结构
@@ -373,6 +441,7 @@ const (
 	SettingKeyMaxFileSize         = "max_file_size"         // 最大文件大小
 	SettingKeyAllowedFileTypes    = "allowed_file_types"    // 允许的文件类型
 	SettingKeyStorageType         = "storage_type"          // 默认存储类型
+	SettingKeyShareTransferLimit  = "share_transfer_limit"  // 每个分享每月下载流量上限
 
 	// 文件设置
 	SettingKeyRecycleBinRetention = "recycle_bin_retention" // 回收站保留天数
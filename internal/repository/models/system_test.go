@@ -0,0 +1,61 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	_ "modernc.org/sqlite" // 使用纯Go的SQLite驱动
+
+	basemodels "cloudpan/internal/pkg/database/models"
+)
+
+func TestAdminAuditLog_Immutable(t *testing.T) {
+	sqlDB, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open sqlite: %v", err)
+	}
+	db, err := gorm.Open(sqlite.Dialector{Conn: sqlDB}, &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open gorm: %v", err)
+	}
+	if err := db.AutoMigrate(&AdminAuditLog{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	log := &AdminAuditLog{
+		ActorID:    1,
+		Action:     "user.suspend",
+		TargetType: "user",
+		TargetID:   "42",
+		Before:     &basemodels.JSONMap{"status": "active"},
+		After:      &basemodels.JSONMap{"status": "suspended"},
+		Reason:     "spam",
+	}
+	if err := db.Create(log).Error; err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if log.CreatedAt.IsZero() {
+		t.Error("CreatedAt should be set on create")
+	}
+
+	if err := db.Model(log).Update("reason", "edited").Error; !errors.Is(err, ErrAdminAuditLogImmutable) {
+		t.Errorf("Update error = %v, want ErrAdminAuditLogImmutable", err)
+	}
+	if err := db.Save(log).Error; !errors.Is(err, ErrAdminAuditLogImmutable) {
+		t.Errorf("Save error = %v, want ErrAdminAuditLogImmutable", err)
+	}
+	if err := db.Delete(log).Error; !errors.Is(err, ErrAdminAuditLogImmutable) {
+		t.Errorf("Delete error = %v, want ErrAdminAuditLogImmutable", err)
+	}
+
+	var stored AdminAuditLog
+	if err := db.First(&stored, log.ID).Error; err != nil {
+		t.Fatalf("First failed: %v", err)
+	}
+	if stored.Reason != "spam" || (*stored.After)["status"] != "suspended" {
+		t.Errorf("stored log changed: %+v", stored)
+	}
+}
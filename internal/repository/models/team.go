@@ -32,6 +32,11 @@ type Team struct {
 	// 状态信息
 	Status string `gorm:"type:enum('active','inactive','suspended','archived');default:'active'" json:"status"` // 团队状态
 
+	// 删除信息，删除的团队先归档，恢复期限内可以恢复
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`           // 删除(归档)时间
+	ArchivedBy *uint      `json:"archived_by,omitempty"`           // 删除团队的用户ID
+	PurgeAt    *time.Time `gorm:"index" json:"purge_at,omitempty"` // 恢复期限，之后彻底删除
+
 	// 统计信息
 	MemberCount int   `gorm:"default:1" json:"member_count"` // 成员数量
 	FileCount   int64 `gorm:"default:0" json:"file_count"`   // 文件数量
@@ -64,6 +69,11 @@ func (t *Team) IsActive() bool {
 	return t.Status == "active"
 }
 
+// IsArchived 检查团队是否已删除(归档)
+func (t *Team) IsArchived() bool {
+	return t.Status == TeamStatusArchived
+}
+
 // HasStorageSpace 检查是否有足够存储空间
 func (t *Team) HasStorageSpace(size int64) bool {
 	return t.StorageUsed+size <= t.StorageQuota
@@ -89,13 +99,13 @@ type TeamMember struct {
 	UserID uint `gorm:"not null;index" json:"user_id"` // 用户ID
 
 	// 角色和权限
-	Role        string              `gorm:"type:enum('owner','admin','member','readonly');default:'member'" json:"role"` // 成员角色
-	Permissions *basemodels.JSONMap `gorm:"type:json" json:"permissions,omitempty"`                                      // 自定义权限
+	Role        string              `gorm:"type:enum('owner','admin','member','viewer');default:'member'" json:"role"` // 成员角色
+	Permissions *basemodels.JSONMap `gorm:"type:json" json:"permissions,omitempty"`                                    // 自定义权限
 
 	// 状态信息
-	Status    string     `gorm:"type:enum('active','inactive','pending','suspended');default:'active'" json:"status"` // 成员状态
-	JoinedAt  *time.Time `json:"joined_at,omitempty"`                                                                 // 加入时间
-	InvitedBy *uint      `gorm:"index" json:"invited_by,omitempty"`                                                   // 邀请人ID
+	Status    string     `gorm:"type:enum('active','inactive','pending','suspended','archived');default:'active'" json:"status"` // 成员状态
+	JoinedAt  *time.Time `json:"joined_at,omitempty"`                                                                            // 加入时间
+	InvitedBy *uint      `gorm:"index" json:"invited_by,omitempty"`                                                              // 邀请人ID
 
 	// 设置信息
 	Nickname        *string `gorm:"type:varchar(100)" json:"nickname,omitempty"` // 团队内昵称
@@ -155,7 +165,7 @@ func (m *TeamMember) CanManageMembers() bool {
 
 // CanManageFiles 检查是否可以管理文件
 func (m *TeamMember) CanManageFiles() bool {
-	return m.Role != "readonly"
+	return m.Role != TeamRoleViewer
 }
 
 // TeamFile 团队文件表结构
@@ -240,9 +250,9 @@ type TeamInvitation struct {
 	Email     *string `gorm:"type:varchar(255);index" json:"email,omitempty"` // 邀请邮箱(未注册用户)
 
 	// 邀请信息
-	InviteCode string `gorm:"type:varchar(100);uniqueIndex;not null" json:"invite_code"`           // 邀请码
-	InviteURL  string `gorm:"type:varchar(500);not null" json:"invite_url"`                        // 邀请链接
-	Role       string `gorm:"type:enum('admin','member','readonly');default:'member'" json:"role"` // 预设角色
+	InviteCode string `gorm:"type:varchar(100);uniqueIndex;not null" json:"invite_code"`         // 邀请码
+	InviteURL  string `gorm:"type:varchar(500);not null" json:"invite_url"`                      // 邀请链接
+	Role       string `gorm:"type:enum('admin','member','viewer');default:'member'" json:"role"` // 预设角色
 
 	// 消息信息
 	Message *string `gorm:"type:text" json:"message,omitempty"` // 邀请消息
@@ -327,15 +337,15 @@ const (
 	TeamStatusActive    = "active"    // 活跃
 	TeamStatusInactive  = "inactive"  // 未活跃
 	TeamStatusSuspended = "suspended" // 暂停
-	TeamStatusArchived  = "archived"  // 归档
+	TeamStatusArchived  = "archived"  // 归档(已删除，恢复期限内可以恢复)
 )
 
 // 团队成员角色常量
 const (
-	TeamRoleOwner    = "owner"    // 所有者
-	TeamRoleAdmin    = "admin"    // 管理员
-	TeamRoleMember   = "member"   // 普通成员
-	TeamRoleReadonly = "readonly" // 只读成员
+	TeamRoleOwner  = "owner"  // 所有者
+	TeamRoleAdmin  = "admin"  // 管理员
+	TeamRoleMember = "member" // 普通成员
+	TeamRoleViewer = "viewer" // 只读成员，只能查看团队文件
 )
 
 // 团队成员状态常量
@@ -344,6 +354,7 @@ const (
 	TeamMemberStatusInactive  = "inactive"  // 未活跃
 	TeamMemberStatusPending   = "pending"   // 待处理
 	TeamMemberStatusSuspended = "suspended" // 暂停
+	TeamMemberStatusArchived  = "archived"  // 团队已删除，恢复前只能查看
 )
 
 // 团队文件权限常量
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"time"
+
+	basemodels "cloudpan/internal/pkg/database/models"
+)
+
+// 文件所有权转移状态
+const (
+	TransferStatusPending   = "pending"   // 等待接收方接受
+	TransferStatusAccepted  = "accepted"  // 接收方已接受，转移未完成(失败后可重试)
+	TransferStatusCompleted = "completed" // 已完成
+	TransferStatusDeclined  = "declined"  // 接收方已拒绝
+	TransferStatusCancelled = "cancelled" // 发起方已取消
+	TransferStatusExpired   = "expired"   // 超过有效期未接受
+)
+
+// 转移时已有分享的处理方式
+const (
+	TransferShareKeep   = "keep"   // 分享保持有效，分享者改为接收方
+	TransferShareRevoke = "revoke" // 停用有效的分享
+)
+
+// FileTransfer 文件和文件夹所有权转移记录表结构
+//
+// 发起方把自己的文件或文件夹(连同全部子项)转给另一个用户，接收方接受后文件移到接收方的根目录，
+// 存储用量随之从发起方转到接收方。管理员强制转移(用于用户离职交接)不需要接收方接受，直接完成。
+// 记录在转移完成或结束后保留，作为双方的转移历史
+type FileTransfer struct {
+	basemodels.BaseModelWithoutSoftDelete
+	FileID     uint   `gorm:"not null;index" json:"file_id"`                                                                                                                                                                         // 转移的文件或文件夹ID
+	FileName   string `gorm:"type:varchar(255);not null" json:"file_name"`                                                                                                                                                           // 发起时的文件名，完成后为接收方空间中的名称
+	IsFolder   bool   `gorm:"default:false" json:"is_folder"`                                                                                                                                                                        // 是否为文件夹
+	FromUserID uint   `gorm:"not null;index:idx_file_transfers_from,priority:1" json:"from_user_id"`                                                                                                                                 // 原所有者ID
+	ToUserID   uint   `gorm:"not null;index:idx_file_transfers_to,priority:1" json:"to_user_id"`                                                                                                                                     // 接收方ID
+	Status     string `gorm:"type:enum('pending','accepted','completed','declined','cancelled','expired');not null;default:'pending';index:idx_file_transfers_from,priority:2;index:idx_file_transfers_to,priority:2" json:"status"` // 转移状态
+	ShareMode  string `gorm:"type:enum('keep','revoke');not null;default:'keep'" json:"share_mode"`                                                                                                                                  // 已有分享的处理方式
+
+	Message  *string `gorm:"type:varchar(500)" json:"message,omitempty"` // 发起方留言，强制转移时为管理员填写的原因
+	ForcedBy *uint   `json:"forced_by,omitempty"`                        // 强制转移的管理员ID，为空表示用户发起
+
+	Items int64   `gorm:"default:0" json:"items"`                   // 完成时转移的文件数(含文件夹)
+	Size  int64   `gorm:"default:0" json:"size"`                    // 完成时转移的存储用量(含历史版本)
+	Error *string `gorm:"type:varchar(500)" json:"error,omitempty"` // 最近一次完成失败的原因
+
+	ExpiresAt   *time.Time `gorm:"index" json:"expires_at,omitempty"` // 等待接受的截止时间
+	RespondedAt *time.Time `json:"responded_at,omitempty"`            // 接受、拒绝或取消的时间
+	CompletedAt *time.Time `json:"completed_at,omitempty"`            // 完成时间
+}
+
+// TableName 文件所有权转移表名
+func (FileTransfer) TableName() string {
+	return "file_transfers"
+}
+
+// IsOpen 转移是否仍在进行中(等待接受或已接受未完成)
+func (t *FileTransfer) IsOpen() bool {
+	return t.Status == TransferStatusPending || t.Status == TransferStatusAccepted
+}
+
+// IsValidTransferShareMode 检查是否为有效的分享处理方式
+func IsValidTransferShareMode(mode string) bool {
+	return mode == TransferShareKeep || mode == TransferShareRevoke
+}
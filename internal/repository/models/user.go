@@ -1,9 +1,12 @@
 package models
 
 import (
+	"strconv"
+	"strings"
 	"time"
 
 	basemodels "cloudpan/internal/pkg/database/models"
+	"cloudpan/internal/pkg/indexing"
 
 	"gorm.io/gorm"
 )
@@ -12,13 +15,13 @@ import (
 type User struct {
 	basemodels.BaseModel
 	// 基本信息
-	UUID         string  `gorm:"type:char(36);uniqueIndex;not null" json:"uuid"`         // 用户唯一标识符
-	Email        string  `gorm:"type:varchar(255);uniqueIndex;not null" json:"email"`    // 邮箱地址
-	Username     string  `gorm:"type:varchar(100);uniqueIndex;not null" json:"username"` // 用户名
-	PasswordHash string  `gorm:"type:varchar(255);not null" json:"-"`                    // 密码哈希值
-	Phone        *string `gorm:"type:varchar(20);index" json:"phone,omitempty"`          // 手机号码
-	AvatarURL    *string `gorm:"type:varchar(500)" json:"avatar_url,omitempty"`          // 头像URL
-	DisplayName  *string `gorm:"type:varchar(100)" json:"display_name,omitempty"`        // 显示名称
+	UUID         string  `gorm:"type:char(36);uniqueIndex;not null" json:"uuid"`                // 用户唯一标识符
+	Email        string  `gorm:"type:varchar(255);uniqueIndex;not null" json:"email"`           // 邮箱地址
+	Username     string  `gorm:"type:varchar(100);uniqueIndex;not null" json:"username"`        // 用户名
+	PasswordHash string  `gorm:"type:varchar(255);not null" json:"-"`                           // 密码哈希值
+	Phone        *string `gorm:"type:varchar(512);serializer:encrypted" json:"phone,omitempty"` // 手机号码(加密存储)
+	AvatarURL    *string `gorm:"type:varchar(500)" json:"avatar_url,omitempty"`                 // 头像URL
+	DisplayName  *string `gorm:"type:varchar(100)" json:"display_name,omitempty"`               // 显示名称
 
 	// 状态信息
 	Status          string     `gorm:"type:enum('active','inactive','suspended','deleted');default:'active';index" json:"status"` // 用户状态
@@ -33,9 +36,16 @@ type User struct {
 
 	// 安全信息
 	MFAEnabled     bool    `gorm:"default:false" json:"mfa_enabled"`                               // 多因素认证启用状态
-	MFASecret      *string `gorm:"type:varchar(255)" json:"-"`                                     // MFA密钥
+	MFASecret      *string `gorm:"type:varchar(512);serializer:encrypted" json:"-"`                // MFA密钥(加密存储)
 	MFAType        string  `gorm:"type:enum('totp','sms','email');default:'totp'" json:"mfa_type"` // MFA类型
-	MFABackupCodes *string `gorm:"type:text" json:"-"`                                             // MFA备用码
+	MFABackupCodes *string `gorm:"type:text;serializer:encrypted" json:"-"`                        // MFA备用码(加密存储)
+
+	// 强制重置密码
+	PasswordResetRequired    bool       `gorm:"default:false;index" json:"password_reset_required"` // 是否必须重置密码(标记期间禁止登录)
+	PasswordResetRequestedAt *time.Time `json:"password_reset_requested_at,omitempty"`              // 管理员要求重置密码的时间
+
+	// 计划删除
+	DeletionScheduledAt *time.Time `gorm:"index" json:"deletion_scheduled_at,omitempty"` // 账户彻底删除的计划时间，之前可重新激活
 
 	// 时间信息
 	LastLoginAt       *time.Time `json:"last_login_at,omitempty"`                         // 最后登录时间
@@ -70,11 +80,57 @@ func (u *User) BeforeCreate(tx *gorm.DB) error {
 	return u.BaseModel.BeforeCreate(tx)
 }
 
+// AfterCreate 创建后钩子，暂存索引文档
+func (u *User) AfterCreate(tx *gorm.DB) error {
+	return indexing.StageUpsert(tx, u.ID, &User{})
+}
+
+// AfterUpdate 更新后钩子，重新加载并暂存索引文档
+func (u *User) AfterUpdate(tx *gorm.DB) error {
+	return indexing.StageUpsert(tx, u.ID, &User{})
+}
+
+// AfterDelete 删除后钩子，暂存删除文档
+func (u *User) AfterDelete(tx *gorm.DB) error {
+	indexing.StageDelete(tx, indexing.DocumentTypeUser, u.ID, u.Version)
+	return nil
+}
+
+// IndexDocument 生成用户索引文档(不包含手机号、MFA等敏感信息)
+func (u *User) IndexDocument() indexing.Document {
+	return indexing.Document{
+		Type:      indexing.DocumentTypeUser,
+		ID:        u.ID,
+		Operation: indexing.OperationUpsert,
+		Version:   u.Version,
+		Fields: map[string]interface{}{
+			"uuid":         u.UUID,
+			"email":        u.Email,
+			"username":     u.Username,
+			"display_name": stringValue(u.DisplayName),
+			"avatar_url":   stringValue(u.AvatarURL),
+			"status":       u.Status,
+			"updated_at":   u.UpdatedAt,
+		},
+		OccurredAt: time.Now(),
+	}
+}
+
+// EncryptionSubject 敏感字段加密主体，每个用户的手机号和MFA密钥使用独立派生的密钥
+func (u *User) EncryptionSubject() string {
+	return u.UUID
+}
+
 // IsActive 检查用户是否激活
 func (u *User) IsActive() bool {
 	return u.Status == "active"
 }
 
+// IsPendingDeletion 检查账户是否已计划删除且仍可重新激活
+func (u *User) IsPendingDeletion(now time.Time) bool {
+	return u.Status == "deleted" && u.DeletionScheduledAt != nil && now.Before(*u.DeletionScheduledAt)
+}
+
 // IsSuspended 检查用户是否被暂停
 func (u *User) IsSuspended() bool {
 	return u.Status == "suspended"
@@ -93,6 +149,98 @@ func (u *User) HasStorageSpace(size int64) bool {
 	return u.StorageUsed+size <= u.StorageQuota
 }
 
+// PreferredLanguage 返回已加载的界面语言偏好，未设置或未预加载偏好时返回空字符串
+func (u *User) PreferredLanguage() string {
+	for i := range u.Preferences {
+		pref := &u.Preferences[i]
+		if pref.Category == PreferenceCategoryUI && pref.Key == PreferenceKeyLanguage {
+			return pref.GetStringValue()
+		}
+	}
+	return ""
+}
+
+// UserTwoFactor 用户两步验证(TOTP)登记表结构
+//
+// 用户开始登记时写入未启用的记录，使用验证器应用生成的验证码确认后启用。
+// 启用状态同步写入用户表的 mfa_enabled 字段
+type UserTwoFactor struct {
+	basemodels.BaseModelWithoutSoftDelete
+	UserID       uint       `gorm:"not null;uniqueIndex" json:"user_id"`                      // 用户ID
+	Secret       string     `gorm:"type:varchar(512);not null;serializer:encrypted" json:"-"` // TOTP密钥(Base32，加密存储)
+	Enabled      bool       `gorm:"default:false" json:"enabled"`                             // 是否已启用
+	EnabledAt    *time.Time `json:"enabled_at,omitempty"`                                     // 启用时间
+	LastUsedStep int64      `gorm:"default:0" json:"-"`                                       // 最近一次通过验证的时间步，不晚于它的验证码视为重放
+	BackupCodes  string     `gorm:"type:text" json:"-"`                                       // 备用码哈希(逗号分隔)，使用后移除
+}
+
+// TableName 用户两步验证表名
+func (UserTwoFactor) TableName() string {
+	return "user_two_factors"
+}
+
+// EncryptionSubject 敏感字段加密主体，每个用户的TOTP密钥使用独立派生的密钥
+func (t *UserTwoFactor) EncryptionSubject() string {
+	return strconv.FormatUint(uint64(t.UserID), 10)
+}
+
+// BackupCodeHashes 返回未使用的备用码哈希
+func (t *UserTwoFactor) BackupCodeHashes() []string {
+	if t.BackupCodes == "" {
+		return nil
+	}
+	return strings.Split(t.BackupCodes, ",")
+}
+
+// StorageReservation 上传存储空间预留表结构
+//
+// 上传开始时按声明大小预留配额，完成时转为已用空间，失败或过期时释放。
+// 计算剩余空间时扣除已用空间和未过期的预留，避免并发上传合计超出配额
+type StorageReservation struct {
+	basemodels.BaseModelWithoutSoftDelete
+	UserID    uint      `gorm:"not null;index" json:"user_id"`                          // 用户ID
+	UploadID  string    `gorm:"type:varchar(64);not null;uniqueIndex" json:"upload_id"` // 上传任务ID
+	Size      int64     `gorm:"not null" json:"size"`                                   // 预留大小(字节)
+	ExpiresAt time.Time `gorm:"not null;index" json:"expires_at"`                       // 过期时间，过期后不再占用配额
+}
+
+// TableName 存储空间预留表名
+func (StorageReservation) TableName() string {
+	return "storage_reservations"
+}
+
+// 长期未登录处置的下一步
+const (
+	InactivityStepAction   = "action"   // 等待执行配置的处置(降低配额或冻结)
+	InactivityStepDeletion = "deletion" // 等待计划删除账户
+	InactivityStepDone     = "done"     // 全部步骤已执行
+)
+
+// UserInactivity 长期未登录账户的处置状态表结构
+//
+// 账户进入提醒期时创建，记录已发送的提醒、执行的处置和处置前的配额，
+// 用户重新登录时据此恢复并删除记录。last_active_at 与用户当前的最后活动时间不一致时视为已重新活动
+type UserInactivity struct {
+	UserID              uint       `gorm:"primaryKey;autoIncrement:false" json:"user_id"` // 用户ID
+	LastActiveAt        time.Time  `gorm:"not null" json:"last_active_at"`                // 进入提醒期时用户的最后活动时间
+	Step                string     `gorm:"type:varchar(20);not null" json:"step"`         // 下一步：action/deletion/done
+	StepDueAt           *time.Time `gorm:"index" json:"step_due_at,omitempty"`            // 下一步的执行时间，不早于最后一次提醒后的提醒期
+	WarningsSent        int        `gorm:"default:0" json:"warnings_sent"`                // 当前步骤已发送的提醒次数
+	LastWarnedAt        *time.Time `json:"last_warned_at,omitempty"`                      // 最近一次发送提醒的时间
+	OriginalQuota       *int64     `json:"original_quota,omitempty"`                      // 降低配额前的存储配额，恢复时使用
+	DowngradedAt        *time.Time `json:"downgraded_at,omitempty"`                       // 降低配额的时间
+	FrozenAt            *time.Time `json:"frozen_at,omitempty"`                           // 冻结账户的时间
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty"`               // 计划删除账户的时间
+	CreatedAt           time.Time  `json:"created_at"`                                    // 进入提醒期的时间
+	UpdatedAt           time.Time  `json:"updated_at"`                                    // 更新时间
+	User                *User      `gorm:"foreignKey:UserID" json:"user,omitempty"`       // 用户，列表接口附带
+}
+
+// TableName 长期未登录处置状态表名
+func (UserInactivity) TableName() string {
+	return "user_inactivities"
+}
+
 // UserSession 用户会话表结构
 type UserSession struct {
 	basemodels.BaseModel
@@ -238,9 +386,10 @@ const (
 	PreferenceKeyFileView = "file_view" // 文件视图模式
 
 	// 文件设置
-	PreferenceKeyAutoSync      = "auto_sync"      // 自动同步
-	PreferenceKeyUploadQuality = "upload_quality" // 上传质量
-	PreferenceKeyDownloadPath  = "download_path"  // 下载路径
+	PreferenceKeyAutoSync       = "auto_sync"            // 自动同步
+	PreferenceKeyUploadQuality  = "upload_quality"       // 上传质量
+	PreferenceKeyDownloadPath   = "download_path"        // 下载路径
+	PreferenceKeyTrashRetention = "trash_retention_days" // 回收站保留天数
 
 	// 通知设置
 	PreferenceKeyEmailNotify = "email_notify" // 邮件通知
@@ -114,9 +114,9 @@ const (
 type EmailTemplate struct {
 	basemodels.BaseModel
 	// 基本信息
-	UUID string `gorm:"type:char(36);uniqueIndex;not null" json:"uuid"` // 模板唯一标识符
-	Type string `gorm:"type:varchar(50);not null;unique" json:"type"`   // 模板类型
-	Name string `gorm:"type:varchar(100);not null" json:"name"`         // 模板名称
+	UUID string `gorm:"type:char(36);uniqueIndex;not null" json:"uuid"`                                                // 模板唯一标识符
+	Type string `gorm:"type:varchar(50);not null;uniqueIndex:uk_email_templates_type_language,priority:1" json:"type"` // 模板类型
+	Name string `gorm:"type:varchar(100);not null" json:"name"`                                                        // 模板名称
 
 	// 模板内容
 	Subject     string  `gorm:"type:varchar(255);not null" json:"subject"` // 邮件主题
@@ -130,7 +130,7 @@ type EmailTemplate struct {
 	IsActive bool `gorm:"default:true" json:"is_active"` // 是否启用
 
 	// 语言设置
-	Language string `gorm:"type:varchar(10);default:'zh-CN'" json:"language"` // 语言代码
+	Language string `gorm:"type:varchar(10);default:'zh-CN';uniqueIndex:uk_email_templates_type_language,priority:2" json:"language"` // 语言代码
 
 	// 更新信息
 	UpdatedBy *uint `json:"updated_by,omitempty"` // 更新者ID
@@ -0,0 +1,19 @@
+package notification
+
+import (
+	"context"
+
+	"cloudpan/internal/repository/models"
+)
+
+// NotificationRepository 站内通知数据仓库接口
+//
+// 提供通知的写入操作，供安全告警等模块向用户发送站内通知
+//
+// 使用示例：
+//
+//	repo := NewNotificationRepository(db)
+//	err := repo.Create(ctx, &models.Notification{UserID: userID, Type: models.NotificationTypeSecurityAlert, Title: title})
+type NotificationRepository interface {
+	Create(ctx context.Context, notification *models.Notification) error
+}
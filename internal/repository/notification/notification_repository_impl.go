@@ -0,0 +1,32 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/database"
+	"cloudpan/internal/repository/models"
+)
+
+// notificationRepository 站内通知数据仓库实现
+type notificationRepository struct {
+	db *gorm.DB
+}
+
+// NewNotificationRepository 创建站内通知数据仓库实例
+func NewNotificationRepository(db *gorm.DB) NotificationRepository {
+	return &notificationRepository{
+		db: db,
+	}
+}
+
+// Create 创建通知
+func (r *notificationRepository) Create(ctx context.Context, notification *models.Notification) error {
+	if notification == nil || notification.UserID == 0 {
+		return fmt.Errorf("通知接收者不能为空")
+	}
+
+	return database.Conn(ctx, r.db).Create(notification).Error
+}
@@ -0,0 +1,34 @@
+package system
+
+import (
+	"context"
+	"time"
+
+	"cloudpan/internal/repository/models"
+)
+
+// AdminAuditFilter 管理员审计日志查询条件，零值字段不参与过滤
+type AdminAuditFilter struct {
+	ActorID    uint      // 操作的管理员ID
+	Action     string    // 操作类型
+	TargetType string    // 操作对象类型
+	TargetID   string    // 操作对象ID
+	From       time.Time // 起始时间(包含)
+	To         time.Time // 结束时间(不包含)
+}
+
+// AdminAuditRepository 管理员审计日志数据仓库接口
+//
+// 审计日志只允许追加和查询，不提供修改和删除：
+// 1. 追加写入：批量写入审计记录
+// 2. 条件查询：按管理员、操作类型、操作对象和时间范围分页查询，按时间倒序
+//
+// 使用示例：
+//
+//	repo := NewAdminAuditRepository(db)
+//	err := repo.Create(ctx, logs)
+//	logs, total, err := repo.List(ctx, AdminAuditFilter{Action: "user.suspend"}, 20, 0)
+type AdminAuditRepository interface {
+	Create(ctx context.Context, logs []*models.AdminAuditLog) error
+	List(ctx context.Context, filter AdminAuditFilter, limit, offset int) ([]*models.AdminAuditLog, int64, error)
+}
@@ -0,0 +1,72 @@
+package system
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/database"
+	"cloudpan/internal/repository/models"
+)
+
+// adminAuditBatchSize 批量写入时每条SQL包含的记录数
+const adminAuditBatchSize = 500
+
+// adminAuditRepository 管理员审计日志数据仓库实现
+type adminAuditRepository struct {
+	db *gorm.DB
+}
+
+// NewAdminAuditRepository 创建管理员审计日志数据仓库实例
+func NewAdminAuditRepository(db *gorm.DB) AdminAuditRepository {
+	return &adminAuditRepository{
+		db: db,
+	}
+}
+
+// Create 批量写入审计记录
+func (r *adminAuditRepository) Create(ctx context.Context, logs []*models.AdminAuditLog) error {
+	if len(logs) == 0 {
+		return nil
+	}
+	if err := database.Conn(ctx, r.db).CreateInBatches(logs, adminAuditBatchSize).Error; err != nil {
+		return fmt.Errorf("写入管理员审计日志失败: %w", err)
+	}
+	return nil
+}
+
+// List 按条件分页查询审计记录，按时间倒序
+func (r *adminAuditRepository) List(ctx context.Context, filter AdminAuditFilter, limit, offset int) ([]*models.AdminAuditLog, int64, error) {
+	query := database.Conn(ctx, r.db).Model(&models.AdminAuditLog{})
+	if filter.ActorID != 0 {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.TargetType != "" {
+		query = query.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		query = query.Where("target_id = ?", filter.TargetID)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("created_at < ?", filter.To)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var logs []*models.AdminAuditLog
+	err := query.Order("created_at DESC, id DESC").Limit(limit).Offset(offset).Find(&logs).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return logs, total, nil
+}
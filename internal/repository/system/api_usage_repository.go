@@ -0,0 +1,23 @@
+package system
+
+import (
+	"context"
+
+	"cloudpan/internal/repository/models"
+)
+
+// APIUsageRepository API用量日汇总数据仓库接口
+//
+// 提供API用量的累加写入和按日期范围查询：
+// 1. 累加写入：同一 用户+日期+API密钥+接口 的记录在原有计数上累加
+// 2. 用量查询：按用户和日期范围(YYYY-MM-DD，包含首尾)查询日汇总记录
+//
+// 使用示例：
+//
+//	repo := NewAPIUsageRepository(db)
+//	err := repo.AddDaily(ctx, rows)
+//	rows, err := repo.ListDaily(ctx, userID, "2024-03-01", "2024-03-31")
+type APIUsageRepository interface {
+	AddDaily(ctx context.Context, rows []*models.APIUsageDaily) error
+	ListDaily(ctx context.Context, userID uint, from, to string) ([]*models.APIUsageDaily, error)
+}
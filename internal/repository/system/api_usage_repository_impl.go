@@ -0,0 +1,68 @@
+package system
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"cloudpan/internal/pkg/database"
+	"cloudpan/internal/repository/models"
+)
+
+// apiUsageBatchSize 累加写入时每条SQL包含的记录数
+const apiUsageBatchSize = 200
+
+// apiUsageRepository API用量日汇总数据仓库实现
+type apiUsageRepository struct {
+	db *gorm.DB
+}
+
+// NewAPIUsageRepository 创建API用量日汇总数据仓库实例
+func NewAPIUsageRepository(db *gorm.DB) APIUsageRepository {
+	return &apiUsageRepository{
+		db: db,
+	}
+}
+
+// AddDaily 累加写入日汇总记录，记录已存在时在原有计数上累加
+func (r *apiUsageRepository) AddDaily(ctx context.Context, rows []*models.APIUsageDaily) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	err := database.Conn(ctx, r.db).
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "user_id"}, {Name: "date"}, {Name: "api_key_id"}, {Name: "endpoint"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"requests":   gorm.Expr("requests + VALUES(requests)"),
+				"errors":     gorm.Expr("errors + VALUES(errors)"),
+				"bytes_in":   gorm.Expr("bytes_in + VALUES(bytes_in)"),
+				"bytes_out":  gorm.Expr("bytes_out + VALUES(bytes_out)"),
+				"updated_at": gorm.Expr("VALUES(updated_at)"),
+			}),
+		}).
+		CreateInBatches(rows, apiUsageBatchSize).Error
+	if err != nil {
+		return fmt.Errorf("写入API用量失败: %w", err)
+	}
+	return nil
+}
+
+// ListDaily 查询用户在日期范围内(包含首尾)的日汇总记录，按日期和接口排序
+func (r *apiUsageRepository) ListDaily(ctx context.Context, userID uint, from, to string) ([]*models.APIUsageDaily, error) {
+	if userID == 0 {
+		return nil, fmt.Errorf("用户ID不能为空")
+	}
+
+	var rows []*models.APIUsageDaily
+	err := database.Conn(ctx, r.db).
+		Where("user_id = ? AND date BETWEEN ? AND ?", userID, from, to).
+		Order("date ASC, api_key_id ASC, endpoint ASC").
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
@@ -0,0 +1,34 @@
+package system
+
+import (
+	"context"
+	"time"
+
+	"cloudpan/internal/repository/models"
+)
+
+// AuditLogFilter 安全审计日志查询条件，零值字段不参与过滤
+type AuditLogFilter struct {
+	UserID   uint      // 操作用户ID
+	Action   string    // 操作类型
+	Category string    // 操作分类
+	Result   string    // 操作结果
+	From     time.Time // 起始时间(包含)
+	To       time.Time // 结束时间(不包含)
+}
+
+// AuditLogRepository 安全审计日志数据仓库接口
+//
+// 审计日志只允许追加和查询，不提供修改和删除：
+// 1. 追加写入：批量写入审计记录
+// 2. 条件查询：按用户、操作类型、分类、结果和时间范围分页查询，按时间倒序
+//
+// 使用示例：
+//
+//	repo := NewAuditLogRepository(db)
+//	err := repo.Create(ctx, logs)
+//	logs, total, err := repo.List(ctx, AuditLogFilter{UserID: 42, Category: "auth"}, 20, 0)
+type AuditLogRepository interface {
+	Create(ctx context.Context, logs []*models.AuditLog) error
+	List(ctx context.Context, filter AuditLogFilter, limit, offset int) ([]*models.AuditLog, int64, error)
+}
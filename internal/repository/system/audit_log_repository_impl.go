@@ -0,0 +1,72 @@
+package system
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/database"
+	"cloudpan/internal/repository/models"
+)
+
+// auditLogBatchSize 批量写入时每条SQL包含的记录数
+const auditLogBatchSize = 500
+
+// auditLogRepository 安全审计日志数据仓库实现
+type auditLogRepository struct {
+	db *gorm.DB
+}
+
+// NewAuditLogRepository 创建安全审计日志数据仓库实例
+func NewAuditLogRepository(db *gorm.DB) AuditLogRepository {
+	return &auditLogRepository{
+		db: db,
+	}
+}
+
+// Create 批量写入审计记录
+func (r *auditLogRepository) Create(ctx context.Context, logs []*models.AuditLog) error {
+	if len(logs) == 0 {
+		return nil
+	}
+	if err := database.Conn(ctx, r.db).CreateInBatches(logs, auditLogBatchSize).Error; err != nil {
+		return fmt.Errorf("写入安全审计日志失败: %w", err)
+	}
+	return nil
+}
+
+// List 按条件分页查询审计记录，按时间倒序
+func (r *auditLogRepository) List(ctx context.Context, filter AuditLogFilter, limit, offset int) ([]*models.AuditLog, int64, error) {
+	query := database.Conn(ctx, r.db).Model(&models.AuditLog{})
+	if filter.UserID != 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.Category != "" {
+		query = query.Where("category = ?", filter.Category)
+	}
+	if filter.Result != "" {
+		query = query.Where("result = ?", filter.Result)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("created_at < ?", filter.To)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var logs []*models.AuditLog
+	err := query.Order("created_at DESC, id DESC").Limit(limit).Offset(offset).Find(&logs).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return logs, total, nil
+}
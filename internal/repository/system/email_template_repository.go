@@ -0,0 +1,17 @@
+package system
+
+import (
+	"cloudpan/internal/pkg/email"
+)
+
+// EmailTemplateRepository 邮件模板数据仓库接口
+//
+// 保存管理员在线修改的邮件模板，按 类型(模板名称)+语言 区分版本，实现 email.TemplateStore，
+// 供邮件服务覆盖内置和模板目录中的模板
+//
+// 使用示例：
+//
+//	email.SetGlobalTemplateStore(NewEmailTemplateRepository(db))
+type EmailTemplateRepository interface {
+	email.TemplateStore
+}
@@ -0,0 +1,131 @@
+package system
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"cloudpan/internal/pkg/database"
+	basemodels "cloudpan/internal/pkg/database/models"
+	"cloudpan/internal/pkg/email"
+	"cloudpan/internal/repository/models"
+)
+
+// emailTemplateRepository 邮件模板数据仓库实现
+//
+// email_templates 表没有 deleted_at 字段，查询和删除都不使用软删除
+type emailTemplateRepository struct {
+	db *gorm.DB
+}
+
+// NewEmailTemplateRepository 创建邮件模板数据仓库实例
+func NewEmailTemplateRepository(db *gorm.DB) EmailTemplateRepository {
+	return &emailTemplateRepository{
+		db: db,
+	}
+}
+
+// ListTemplates 获取全部修改过的模板
+func (r *emailTemplateRepository) ListTemplates(ctx context.Context) ([]*email.EmailTemplate, error) {
+	var rows []*models.EmailTemplate
+	err := database.Conn(ctx, r.db).Unscoped().
+		Order("type ASC, language ASC").
+		Find(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询邮件模板失败: %w", err)
+	}
+
+	templates := make([]*email.EmailTemplate, 0, len(rows))
+	for _, row := range rows {
+		templates = append(templates, toEmailTemplate(row))
+	}
+	return templates, nil
+}
+
+// SaveTemplate 保存模板，同一类型和语言已有模板时覆盖内容
+func (r *emailTemplateRepository) SaveTemplate(ctx context.Context, tmpl *email.EmailTemplate) error {
+	row := fromEmailTemplate(tmpl)
+	err := database.Conn(ctx, r.db).
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "type"}, {Name: "language"}},
+			DoUpdates: clause.AssignmentColumns([]string{
+				"name", "subject", "content", "text_content", "variables", "is_active", "updated_by", "updated_at",
+			}),
+		}).
+		Create(row).Error
+	if err != nil {
+		return fmt.Errorf("保存邮件模板失败: %w", err)
+	}
+	return nil
+}
+
+// DeleteTemplate 删除模板，不存在时返回false
+func (r *emailTemplateRepository) DeleteTemplate(ctx context.Context, name, language string) (bool, error) {
+	result := database.Conn(ctx, r.db).Unscoped().
+		Where("type = ? AND language = ?", name, language).
+		Delete(&models.EmailTemplate{})
+	if result.Error != nil {
+		return false, fmt.Errorf("删除邮件模板失败: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// toEmailTemplate 数据库记录转换为邮件服务模板，类型对应模板名称
+func toEmailTemplate(row *models.EmailTemplate) *email.EmailTemplate {
+	tmpl := &email.EmailTemplate{
+		Name:        row.Type,
+		Language:    row.Language,
+		Subject:     row.Subject,
+		HTMLBody:    row.Content,
+		IsActive:    row.IsActive,
+		Description: row.Name,
+		Source:      email.TemplateSourceDatabase,
+	}
+	if row.TextContent != nil {
+		tmpl.TextBody = *row.TextContent
+	}
+	if row.UpdatedBy != nil {
+		tmpl.UpdatedBy = *row.UpdatedBy
+	}
+	if !row.UpdatedAt.IsZero() {
+		updatedAt := row.UpdatedAt
+		tmpl.UpdatedAt = &updatedAt
+	}
+	return tmpl
+}
+
+// fromEmailTemplate 邮件服务模板转换为数据库记录，模板描述保存为名称
+func fromEmailTemplate(tmpl *email.EmailTemplate) *models.EmailTemplate {
+	row := &models.EmailTemplate{
+		Type:     tmpl.Name,
+		Name:     tmpl.Description,
+		Subject:  tmpl.Subject,
+		Content:  tmpl.HTMLBody,
+		IsActive: tmpl.IsActive,
+		Language: tmpl.Language,
+	}
+	if row.Name == "" {
+		row.Name = tmpl.Name
+	}
+	if tmpl.TextBody != "" {
+		text := tmpl.TextBody
+		row.TextContent = &text
+	}
+	if len(tmpl.Variables) > 0 {
+		variables := make(basemodels.JSONMap, len(tmpl.Variables))
+		for name, description := range tmpl.Variables {
+			variables[name] = description
+		}
+		row.Variables = &variables
+	}
+	if tmpl.UpdatedBy != 0 {
+		updatedBy := tmpl.UpdatedBy
+		row.UpdatedBy = &updatedBy
+	}
+	if tmpl.UpdatedAt != nil {
+		row.UpdatedAt = *tmpl.UpdatedAt
+	}
+	return row
+}
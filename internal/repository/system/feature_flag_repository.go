@@ -0,0 +1,21 @@
+package system
+
+import (
+	"context"
+
+	"cloudpan/internal/repository/models"
+)
+
+// FeatureFlagRepository 功能特性标记数据仓库接口
+//
+// 提供特性标记的读取操作，供特性开关服务进行灰度发布和人群定向评估
+//
+// 使用示例：
+//
+//	repo := NewFeatureFlagRepository(db)
+//	flag, err := repo.GetByKey(ctx, "new_uploader")
+//	flags, err := repo.ListAll(ctx)
+type FeatureFlagRepository interface {
+	GetByKey(ctx context.Context, key string) (*models.FeatureFlag, error)
+	ListAll(ctx context.Context) ([]*models.FeatureFlag, error)
+}
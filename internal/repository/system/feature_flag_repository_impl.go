@@ -0,0 +1,53 @@
+package system
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/database"
+	"cloudpan/internal/repository/models"
+)
+
+// featureFlagRepository 功能特性标记数据仓库实现
+type featureFlagRepository struct {
+	db *gorm.DB
+}
+
+// NewFeatureFlagRepository 创建功能特性标记数据仓库实例
+func NewFeatureFlagRepository(db *gorm.DB) FeatureFlagRepository {
+	return &featureFlagRepository{
+		db: db,
+	}
+}
+
+// GetByKey 根据特性键获取特性标记
+func (r *featureFlagRepository) GetByKey(ctx context.Context, key string) (*models.FeatureFlag, error) {
+	if key == "" {
+		return nil, fmt.Errorf("特性键不能为空")
+	}
+
+	var flag models.FeatureFlag
+	err := database.Conn(ctx, r.db).
+		Where("`key` = ?", key).
+		First(&flag).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &flag, nil
+}
+
+// ListAll 获取全部特性标记(包括未启用的)
+func (r *featureFlagRepository) ListAll(ctx context.Context) ([]*models.FeatureFlag, error) {
+	var flags []*models.FeatureFlag
+	err := database.Conn(ctx, r.db).
+		Order("`key` ASC").
+		Find(&flags).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return flags, nil
+}
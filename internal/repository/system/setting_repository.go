@@ -0,0 +1,21 @@
+package system
+
+import (
+	"context"
+
+	"cloudpan/internal/repository/models"
+)
+
+// SettingRepository 系统设置数据仓库接口
+//
+// 提供系统设置的读取操作，供策略解析等模块按分类和键查询管理员配置
+//
+// 使用示例：
+//
+//	repo := NewSettingRepository(db)
+//	setting, err := repo.GetSetting(ctx, models.SettingCategoryStorage, models.SettingKeyMaxFileSize)
+//	settings, err := repo.ListByCategory(ctx, models.SettingCategoryStorage)
+type SettingRepository interface {
+	GetSetting(ctx context.Context, category, key string) (*models.SystemSetting, error)
+	ListByCategory(ctx context.Context, category string) ([]*models.SystemSetting, error)
+}
@@ -0,0 +1,58 @@
+package system
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/database"
+	"cloudpan/internal/repository/models"
+)
+
+// settingRepository 系统设置数据仓库实现
+type settingRepository struct {
+	db *gorm.DB
+}
+
+// NewSettingRepository 创建系统设置数据仓库实例
+func NewSettingRepository(db *gorm.DB) SettingRepository {
+	return &settingRepository{
+		db: db,
+	}
+}
+
+// GetSetting 根据分类和键获取系统设置
+func (r *settingRepository) GetSetting(ctx context.Context, category, key string) (*models.SystemSetting, error) {
+	if category == "" || key == "" {
+		return nil, fmt.Errorf("设置分类和键不能为空")
+	}
+
+	var setting models.SystemSetting
+	err := database.Conn(ctx, r.db).
+		Where("category = ? AND `key` = ?", category, key).
+		First(&setting).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &setting, nil
+}
+
+// ListByCategory 获取分类下的全部系统设置
+func (r *settingRepository) ListByCategory(ctx context.Context, category string) ([]*models.SystemSetting, error) {
+	if category == "" {
+		return nil, fmt.Errorf("设置分类不能为空")
+	}
+
+	var settings []*models.SystemSetting
+	err := database.Conn(ctx, r.db).
+		Where("category = ?", category).
+		Order("sort ASC").
+		Find(&settings).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return settings, nil
+}
@@ -0,0 +1,19 @@
+package system
+
+import (
+	"context"
+
+	"cloudpan/internal/repository/models"
+)
+
+// StoragePolicyRepository 存储策略数据仓库接口
+//
+// 提供存储策略的读取操作，供缓存预热和存储选择等模块加载生效中的策略
+//
+// 使用示例：
+//
+//	repo := NewStoragePolicyRepository(db)
+//	policies, err := repo.ListActive(ctx)
+type StoragePolicyRepository interface {
+	ListActive(ctx context.Context) ([]*models.StoragePolicy, error)
+}
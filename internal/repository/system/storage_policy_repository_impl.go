@@ -0,0 +1,36 @@
+package system
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/database"
+	"cloudpan/internal/repository/models"
+)
+
+// storagePolicyRepository 存储策略数据仓库实现
+type storagePolicyRepository struct {
+	db *gorm.DB
+}
+
+// NewStoragePolicyRepository 创建存储策略数据仓库实例
+func NewStoragePolicyRepository(db *gorm.DB) StoragePolicyRepository {
+	return &storagePolicyRepository{
+		db: db,
+	}
+}
+
+// ListActive 获取启用的存储策略，按优先级从高到低排序
+func (r *storagePolicyRepository) ListActive(ctx context.Context) ([]*models.StoragePolicy, error) {
+	var policies []*models.StoragePolicy
+	err := database.Conn(ctx, r.db).
+		Where("is_active = ?", true).
+		Order("priority DESC, id ASC").
+		Find(&policies).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return policies, nil
+}
@@ -0,0 +1,52 @@
+package team
+
+import (
+	"context"
+	"time"
+
+	"cloudpan/internal/repository/models"
+)
+
+// TeamRepository 团队数据仓库接口
+//
+// 提供团队、成员和团队文件的数据访问操作，包括：
+// 1. 团队管理：创建团队(同时登记所有者)、查询、更新，删除时先归档，恢复期限内可以恢复，过期后彻底删除
+// 2. 成员管理：加入、移除、修改角色和转让所有权，成员变更后重新统计团队成员数
+// 3. 团队文件：共享文件到团队、移出团队和分页查询团队文件
+//
+// 成员和团队文件移除时直接删除记录，同一用户或文件可以再次加入团队。
+// 团队归档时活跃成员的状态改为archived，恢复时改回active
+//
+// 使用示例：
+//
+//	repo := NewTeamRepository(db)
+//	err := repo.Create(ctx, team, &models.TeamMember{UserID: ownerID, Role: models.TeamRoleOwner})
+//	err = repo.AddMember(ctx, member, func(team *models.Team) error { ... })
+//	files, total, err := repo.ListFiles(ctx, teamID, time.Now(), 20, 0)
+type TeamRepository interface {
+	// 团队管理
+	Create(ctx context.Context, team *models.Team, owner *models.TeamMember) error
+	GetByID(ctx context.Context, id uint) (*models.Team, error)
+	Update(ctx context.Context, team *models.Team) error
+	Delete(ctx context.Context, id uint) error
+	Archive(ctx context.Context, id, archivedBy uint, archivedAt, purgeAt time.Time) error
+	Restore(ctx context.Context, id uint) error
+	ListArchivedBefore(ctx context.Context, before time.Time, limit int) ([]*models.Team, error)
+	ListByMember(ctx context.Context, userID uint, limit, offset int) ([]*models.TeamMember, int64, error)
+	ListTeamRolesByMember(ctx context.Context, userID uint) (map[uint]string, error)
+	ListArchivedTeamIDsByMember(ctx context.Context, userID uint) ([]uint, error)
+
+	// 成员管理
+	GetMember(ctx context.Context, teamID, userID uint) (*models.TeamMember, error)
+	ListMembers(ctx context.Context, teamID uint) ([]*models.TeamMember, error)
+	AddMember(ctx context.Context, member *models.TeamMember, check func(team *models.Team) error) error
+	UpdateMemberRole(ctx context.Context, teamID, userID uint, role string) error
+	RemoveMember(ctx context.Context, teamID, userID uint) error
+	TransferOwnership(ctx context.Context, teamID, fromUserID, toUserID uint) error
+
+	// 团队文件
+	AddFile(ctx context.Context, file *models.TeamFile) error
+	GetFile(ctx context.Context, teamID, fileID uint) (*models.TeamFile, error)
+	RemoveFile(ctx context.Context, teamID, fileID uint) error
+	ListFiles(ctx context.Context, teamID uint, now time.Time, limit, offset int) ([]*models.TeamFile, int64, error)
+}
@@ -0,0 +1,378 @@
+package team
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"cloudpan/internal/pkg/database"
+	"cloudpan/internal/repository/models"
+)
+
+// teamRepository 团队数据仓库实现
+type teamRepository struct {
+	db *gorm.DB
+}
+
+// NewTeamRepository 创建团队数据仓库实例
+func NewTeamRepository(db *gorm.DB) TeamRepository {
+	return &teamRepository{
+		db: db,
+	}
+}
+
+// Create 创建团队并登记所有者为成员
+func (r *teamRepository) Create(ctx context.Context, team *models.Team, owner *models.TeamMember) error {
+	return database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(team).Error; err != nil {
+			return fmt.Errorf("创建团队失败: %w", err)
+		}
+		owner.TeamID = team.ID
+		if err := tx.Omit(clause.Associations).Create(owner).Error; err != nil {
+			return fmt.Errorf("添加团队所有者失败: %w", err)
+		}
+		return recountMembers(tx, team.ID)
+	})
+}
+
+// GetByID 根据ID获取团队，不存在时返回 gorm.ErrRecordNotFound
+func (r *teamRepository) GetByID(ctx context.Context, id uint) (*models.Team, error) {
+	var team models.Team
+	if err := database.Conn(ctx, r.db).First(&team, id).Error; err != nil {
+		return nil, err
+	}
+	return &team, nil
+}
+
+// Update 更新团队名称、描述和设置
+func (r *teamRepository) Update(ctx context.Context, team *models.Team) error {
+	err := database.Conn(ctx, r.db).Model(team).
+		Select("name", "description", "avatar", "is_public", "join_approval", "max_members", "updated_at").
+		Updates(team).Error
+	if err != nil {
+		return fmt.Errorf("更新团队失败: %w", err)
+	}
+	return nil
+}
+
+// Delete 彻底删除团队，移除全部成员、团队文件和授予团队的文件权限
+func (r *teamRepository) Delete(ctx context.Context, id uint) error {
+	return database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("team_id = ?", id).Delete(&models.TeamMember{}).Error; err != nil {
+			return fmt.Errorf("移除团队成员失败: %w", err)
+		}
+		if err := tx.Unscoped().Where("team_id = ?", id).Delete(&models.TeamFile{}).Error; err != nil {
+			return fmt.Errorf("移除团队文件失败: %w", err)
+		}
+		err := tx.Where("subject_type = ? AND subject_id = ?", models.GrantSubjectTeam, id).Delete(&models.FileGrant{}).Error
+		if err != nil {
+			return fmt.Errorf("移除团队文件授权失败: %w", err)
+		}
+		if err := tx.Delete(&models.Team{}, id).Error; err != nil {
+			return fmt.Errorf("删除团队失败: %w", err)
+		}
+		return nil
+	})
+}
+
+// Archive 归档团队，活跃成员的状态改为archived；团队不存在或已归档时返回 gorm.ErrRecordNotFound
+func (r *teamRepository) Archive(ctx context.Context, id, archivedBy uint, archivedAt, purgeAt time.Time) error {
+	return database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Team{}).
+			Where("id = ? AND status <> ?", id, models.TeamStatusArchived).
+			Updates(map[string]interface{}{
+				"status":      models.TeamStatusArchived,
+				"archived_at": archivedAt,
+				"archived_by": archivedBy,
+				"purge_at":    purgeAt,
+			})
+		if result.Error != nil {
+			return fmt.Errorf("归档团队失败: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		err := tx.Model(&models.TeamMember{}).
+			Where("team_id = ? AND status = ?", id, models.TeamMemberStatusActive).
+			Update("status", models.TeamMemberStatusArchived).Error
+		if err != nil {
+			return fmt.Errorf("归档团队成员失败: %w", err)
+		}
+		return nil
+	})
+}
+
+// Restore 恢复归档的团队，归档的成员恢复为活跃；团队不存在或未归档时返回 gorm.ErrRecordNotFound
+func (r *teamRepository) Restore(ctx context.Context, id uint) error {
+	return database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Team{}).
+			Where("id = ? AND status = ?", id, models.TeamStatusArchived).
+			Updates(map[string]interface{}{
+				"status":      models.TeamStatusActive,
+				"archived_at": nil,
+				"archived_by": nil,
+				"purge_at":    nil,
+			})
+		if result.Error != nil {
+			return fmt.Errorf("恢复团队失败: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		err := tx.Model(&models.TeamMember{}).
+			Where("team_id = ? AND status = ?", id, models.TeamMemberStatusArchived).
+			Update("status", models.TeamMemberStatusActive).Error
+		if err != nil {
+			return fmt.Errorf("恢复团队成员失败: %w", err)
+		}
+		return nil
+	})
+}
+
+// ListArchivedBefore 查询恢复期限早于 before 的归档团队，按恢复期限排列
+func (r *teamRepository) ListArchivedBefore(ctx context.Context, before time.Time, limit int) ([]*models.Team, error) {
+	var teams []*models.Team
+	err := database.Conn(ctx, r.db).
+		Where("status = ? AND purge_at <= ?", models.TeamStatusArchived, before).
+		Order("purge_at ASC, id ASC").
+		Limit(limit).
+		Find(&teams).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询待删除的团队失败: %w", err)
+	}
+	return teams, nil
+}
+
+// ListByMember 分页查询用户以活跃或归档成员身份加入的团队(含团队)，按加入顺序排列
+func (r *teamRepository) ListByMember(ctx context.Context, userID uint, limit, offset int) ([]*models.TeamMember, int64, error) {
+	db := database.Conn(ctx, r.db).Model(&models.TeamMember{}).
+		Joins("JOIN teams ON teams.id = team_members.team_id AND teams.deleted_at IS NULL").
+		Where("team_members.user_id = ? AND team_members.status IN ?", userID,
+			[]string{models.TeamMemberStatusActive, models.TeamMemberStatusArchived})
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("统计团队失败: %w", err)
+	}
+	var memberships []*models.TeamMember
+	err := db.Preload("Team").
+		Order("team_members.id ASC").
+		Limit(limit).
+		Offset(offset).
+		Find(&memberships).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询团队失败: %w", err)
+	}
+	return memberships, total, nil
+}
+
+// ListTeamRolesByMember 列出用户以活跃成员身份加入的全部团队，返回团队ID到成员角色的映射
+func (r *teamRepository) ListTeamRolesByMember(ctx context.Context, userID uint) (map[uint]string, error) {
+	var rows []struct {
+		TeamID uint
+		Role   string
+	}
+	err := database.Conn(ctx, r.db).Model(&models.TeamMember{}).
+		Select("team_members.team_id, team_members.role").
+		Joins("JOIN teams ON teams.id = team_members.team_id AND teams.deleted_at IS NULL").
+		Where("team_members.user_id = ? AND team_members.status = ?", userID, models.TeamMemberStatusActive).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询用户团队失败: %w", err)
+	}
+	roles := make(map[uint]string, len(rows))
+	for _, row := range rows {
+		roles[row.TeamID] = row.Role
+	}
+	return roles, nil
+}
+
+// ListArchivedTeamIDsByMember 列出用户以归档成员身份所在的已删除团队ID，这些团队授予的文件权限只保留查看
+func (r *teamRepository) ListArchivedTeamIDsByMember(ctx context.Context, userID uint) ([]uint, error) {
+	var teamIDs []uint
+	err := database.Conn(ctx, r.db).Model(&models.TeamMember{}).
+		Joins("JOIN teams ON teams.id = team_members.team_id AND teams.deleted_at IS NULL").
+		Where("team_members.user_id = ? AND team_members.status = ?", userID, models.TeamMemberStatusArchived).
+		Pluck("team_members.team_id", &teamIDs).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询用户已删除的团队失败: %w", err)
+	}
+	return teamIDs, nil
+}
+
+// GetMember 获取用户在团队中的成员记录，不是成员时返回 gorm.ErrRecordNotFound
+func (r *teamRepository) GetMember(ctx context.Context, teamID, userID uint) (*models.TeamMember, error) {
+	var member models.TeamMember
+	err := database.Conn(ctx, r.db).
+		Where("team_id = ? AND user_id = ?", teamID, userID).
+		First(&member).Error
+	if err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+// ListMembers 列出团队成员(含账户)，按加入顺序排列
+func (r *teamRepository) ListMembers(ctx context.Context, teamID uint) ([]*models.TeamMember, error) {
+	var members []*models.TeamMember
+	err := database.Conn(ctx, r.db).
+		Where("team_id = ?", teamID).
+		Preload("User").
+		Order("id ASC").
+		Find(&members).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询团队成员失败: %w", err)
+	}
+	return members, nil
+}
+
+// AddMember 在锁定的团队记录上检查后添加成员
+//
+// 团队不存在时返回 gorm.ErrRecordNotFound，已是成员时返回 gorm.ErrDuplicatedKey；check 返回的错误原样返回
+func (r *teamRepository) AddMember(ctx context.Context, member *models.TeamMember, check func(team *models.Team) error) error {
+	return database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		var team models.Team
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", member.TeamID).First(&team).Error; err != nil {
+			return err
+		}
+		if err := check(&team); err != nil {
+			return err
+		}
+
+		if err := tx.Omit(clause.Associations).Create(member).Error; err != nil {
+			return err
+		}
+		return recountMembers(tx, member.TeamID)
+	})
+}
+
+// UpdateMemberRole 修改成员角色，不是成员时返回 gorm.ErrRecordNotFound
+func (r *teamRepository) UpdateMemberRole(ctx context.Context, teamID, userID uint, role string) error {
+	result := database.Conn(ctx, r.db).Model(&models.TeamMember{}).
+		Where("team_id = ? AND user_id = ?", teamID, userID).
+		Update("role", role)
+	if result.Error != nil {
+		return fmt.Errorf("修改成员角色失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// RemoveMember 将用户移出团队，用户共享到团队的文件保留
+func (r *teamRepository) RemoveMember(ctx context.Context, teamID, userID uint) error {
+	return database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		err := tx.Unscoped().
+			Where("team_id = ? AND user_id = ?", teamID, userID).
+			Delete(&models.TeamMember{}).Error
+		if err != nil {
+			return fmt.Errorf("移除团队成员失败: %w", err)
+		}
+		return recountMembers(tx, teamID)
+	})
+}
+
+// TransferOwnership 将团队转让给另一名成员，原所有者成为管理员
+func (r *teamRepository) TransferOwnership(ctx context.Context, teamID, fromUserID, toUserID uint) error {
+	return database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Team{}).Where("id = ?", teamID).Update("owner_id", toUserID).Error; err != nil {
+			return fmt.Errorf("转让团队失败: %w", err)
+		}
+		err := tx.Model(&models.TeamMember{}).
+			Where("team_id = ? AND user_id = ?", teamID, fromUserID).
+			Update("role", models.TeamRoleAdmin).Error
+		if err != nil {
+			return fmt.Errorf("修改原所有者角色失败: %w", err)
+		}
+		err = tx.Model(&models.TeamMember{}).
+			Where("team_id = ? AND user_id = ?", teamID, toUserID).
+			Update("role", models.TeamRoleOwner).Error
+		if err != nil {
+			return fmt.Errorf("修改新所有者角色失败: %w", err)
+		}
+		return nil
+	})
+}
+
+// AddFile 共享文件到团队，文件已在团队中时返回 gorm.ErrDuplicatedKey
+func (r *teamRepository) AddFile(ctx context.Context, file *models.TeamFile) error {
+	return database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(file).Error; err != nil {
+			return err
+		}
+		return recountFiles(tx, file.TeamID)
+	})
+}
+
+// GetFile 获取团队中的文件记录，不存在时返回 gorm.ErrRecordNotFound
+func (r *teamRepository) GetFile(ctx context.Context, teamID, fileID uint) (*models.TeamFile, error) {
+	var file models.TeamFile
+	err := database.Conn(ctx, r.db).
+		Where("team_id = ? AND file_id = ?", teamID, fileID).
+		First(&file).Error
+	if err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+// RemoveFile 将文件移出团队，文件本身不受影响
+func (r *teamRepository) RemoveFile(ctx context.Context, teamID, fileID uint) error {
+	return database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		err := tx.Unscoped().
+			Where("team_id = ? AND file_id = ?", teamID, fileID).
+			Delete(&models.TeamFile{}).Error
+		if err != nil {
+			return fmt.Errorf("移除团队文件失败: %w", err)
+		}
+		return recountFiles(tx, teamID)
+	})
+}
+
+// ListFiles 分页查询团队中可访问的文件(含文件)，按共享时间倒序
+//
+// 已过期的共享和已删除的文件不列出
+func (r *teamRepository) ListFiles(ctx context.Context, teamID uint, now time.Time, limit, offset int) ([]*models.TeamFile, int64, error) {
+	db := database.Conn(ctx, r.db).Model(&models.TeamFile{}).
+		Joins("JOIN files ON files.id = team_files.file_id AND files.deleted_at IS NULL AND files.status = ?", "active").
+		Where("team_files.team_id = ? AND team_files.status = ?", teamID, models.TeamFileStatusActive).
+		Where("(team_files.expires_at IS NULL OR team_files.expires_at > ?)", now)
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("统计团队文件失败: %w", err)
+	}
+	var files []*models.TeamFile
+	err := db.Preload("File").
+		Order("team_files.shared_at DESC, team_files.id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&files).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询团队文件失败: %w", err)
+	}
+	return files, total, nil
+}
+
+// recountMembers 按成员记录重新统计团队成员数
+func recountMembers(tx *gorm.DB, teamID uint) error {
+	count := tx.Model(&models.TeamMember{}).Select("COUNT(*)").Where("team_id = ?", teamID)
+	if err := tx.Model(&models.Team{}).Where("id = ?", teamID).Update("member_count", count).Error; err != nil {
+		return fmt.Errorf("统计团队成员数失败: %w", err)
+	}
+	return nil
+}
+
+// recountFiles 按团队文件记录重新统计团队文件数
+func recountFiles(tx *gorm.DB, teamID uint) error {
+	count := tx.Model(&models.TeamFile{}).Select("COUNT(*)").Where("team_id = ?", teamID)
+	if err := tx.Model(&models.Team{}).Where("id = ?", teamID).Update("file_count", count).Error; err != nil {
+		return fmt.Errorf("统计团队文件数失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	basemodels "cloudpan/internal/pkg/database/models"
+)
+
+// ErrMergeSourceChanged 合并期间来源账户已不是正常状态(如已被合并或删除)
+var ErrMergeSourceChanged = errors.New("merge source account changed")
+
+// AccountMergeCounts 账户合并中转到目标账户的记录数
+type AccountMergeCounts struct {
+	Teams      int64 `json:"teams"`       // 转移的团队成员身份数(含两个账户都在的团队)
+	OwnedTeams int64 `json:"owned_teams"` // 转移所有权的团队数
+	Identities int64 `json:"identities"`  // 转移的单点登录身份关联数
+}
+
+// AccountMergeRepository 账户合并数据仓库接口
+//
+// 在一个事务中把来源账户的团队成员身份、团队所有权和单点登录身份关联转到目标账户，并把来源账户停用为已合并：
+// 两个账户都在同一团队时保留较高的角色，删除来源账户的成员记录后重新统计团队成员数；
+// 身份关联转移后该身份提供方的用户登录到目标账户
+//
+// 使用示例：
+//
+//	repo := NewAccountMergeRepository(db)
+//	counts, err := repo.MergeInto(ctx, sourceID, targetID, &basemodels.JSONMap{"merged_into": targetID}, time.Now())
+type AccountMergeRepository interface {
+	MergeInto(ctx context.Context, sourceID, targetID uint, profile *basemodels.JSONMap, mergedAt time.Time) (*AccountMergeCounts, error)
+}
@@ -0,0 +1,114 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/database"
+	basemodels "cloudpan/internal/pkg/database/models"
+	"cloudpan/internal/repository/models"
+)
+
+// teamRoleRanks 团队角色的权限高低，合并时保留较高的角色
+var teamRoleRanks = map[string]int{
+	models.TeamRoleViewer: 1,
+	models.TeamRoleMember: 2,
+	models.TeamRoleAdmin:  3,
+	models.TeamRoleOwner:  4,
+}
+
+// accountMergeRepository 账户合并数据仓库实现
+type accountMergeRepository struct {
+	db *gorm.DB
+}
+
+// NewAccountMergeRepository 创建账户合并数据仓库实例
+func NewAccountMergeRepository(db *gorm.DB) AccountMergeRepository {
+	return &accountMergeRepository{
+		db: db,
+	}
+}
+
+// MergeInto 把来源账户的团队和身份关联转到目标账户，来源账户改为已删除并写入 profile
+//
+// 来源账户已不是正常状态时返回 ErrMergeSourceChanged，不做任何修改
+func (r *accountMergeRepository) MergeInto(ctx context.Context, sourceID, targetID uint, profile *basemodels.JSONMap, mergedAt time.Time) (*AccountMergeCounts, error) {
+	counts := &AccountMergeCounts{}
+	err := database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		// 先停用来源账户，并发合并同一账户时只有一个事务能继续
+		result := tx.Model(&models.User{}).
+			Where("id = ? AND status = ?", sourceID, "active").
+			UpdateColumns(map[string]interface{}{
+				"status":                "deleted",
+				"deletion_scheduled_at": nil,
+				"profile":               profile,
+				"updated_at":            mergedAt,
+			})
+		if result.Error != nil {
+			return fmt.Errorf("停用来源账户失败: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrMergeSourceChanged
+		}
+
+		if err := mergeTeamMemberships(tx, sourceID, targetID, counts); err != nil {
+			return err
+		}
+
+		result = tx.Model(&models.Team{}).Where("owner_id = ?", sourceID).UpdateColumn("owner_id", targetID)
+		if result.Error != nil {
+			return fmt.Errorf("转移团队所有权失败: %w", result.Error)
+		}
+		counts.OwnedTeams = result.RowsAffected
+
+		result = tx.Model(&models.SSOIdentity{}).Where("user_id = ?", sourceID).UpdateColumn("user_id", targetID)
+		if result.Error != nil {
+			return fmt.Errorf("转移单点登录身份失败: %w", result.Error)
+		}
+		counts.Identities = result.RowsAffected
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// mergeTeamMemberships 把来源账户的成员记录改到目标账户，目标账户已在团队中时合并为较高的角色
+func mergeTeamMemberships(tx *gorm.DB, sourceID, targetID uint, counts *AccountMergeCounts) error {
+	var memberships []*models.TeamMember
+	if err := tx.Where("user_id = ?", sourceID).Find(&memberships).Error; err != nil {
+		return fmt.Errorf("查询团队成员失败: %w", err)
+	}
+
+	for _, membership := range memberships {
+		var existing models.TeamMember
+		err := tx.Where("team_id = ? AND user_id = ?", membership.TeamID, targetID).First(&existing).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if err := tx.Model(membership).UpdateColumn("user_id", targetID).Error; err != nil {
+				return fmt.Errorf("转移团队成员失败: %w", err)
+			}
+		case err != nil:
+			return fmt.Errorf("查询团队成员失败: %w", err)
+		default:
+			if teamRoleRanks[membership.Role] > teamRoleRanks[existing.Role] {
+				if err := tx.Model(&existing).UpdateColumn("role", membership.Role).Error; err != nil {
+					return fmt.Errorf("修改团队成员角色失败: %w", err)
+				}
+			}
+			if err := tx.Unscoped().Delete(membership).Error; err != nil {
+				return fmt.Errorf("删除团队成员失败: %w", err)
+			}
+			if err := recountTeamMembers(tx, membership.TeamID); err != nil {
+				return err
+			}
+		}
+		counts.Teams++
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package user
+
+import (
+	"context"
+	"time"
+
+	"cloudpan/internal/repository/models"
+)
+
+// APIKeyRepository 用户API密钥数据仓库接口
+//
+// 密钥按哈希查找，创建时锁定用户记录检查密钥数量上限，撤销时删除记录
+//
+// 使用示例：
+//
+//	repo := NewAPIKeyRepository(db)
+//	created, err := repo.Create(ctx, &models.APIKey{UserID: userID, Name: "backup", KeyHash: hash, Scopes: "read"}, 10)
+//	key, err := repo.GetByHash(ctx, hash)
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *models.APIKey, limit int) (bool, error)
+	ListByUser(ctx context.Context, userID uint) ([]*models.APIKey, error)
+	GetByHash(ctx context.Context, keyHash string) (*models.APIKey, error)
+	Delete(ctx context.Context, userID, id uint) (*models.APIKey, error)
+	TouchLastUsed(ctx context.Context, id uint, ip string, usedAt time.Time) error
+}
@@ -0,0 +1,100 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"cloudpan/internal/pkg/database"
+	"cloudpan/internal/repository/models"
+)
+
+// apiKeyRepository 用户API密钥数据仓库实现
+type apiKeyRepository struct {
+	db *gorm.DB
+}
+
+// NewAPIKeyRepository 创建用户API密钥数据仓库实例
+func NewAPIKeyRepository(db *gorm.DB) APIKeyRepository {
+	return &apiKeyRepository{
+		db: db,
+	}
+}
+
+// Create 创建密钥，用户已有 limit 个密钥时不创建并返回false；limit 为0表示不限制
+//
+// 锁定用户记录后统计，避免并发创建超过上限
+func (r *apiKeyRepository) Create(ctx context.Context, key *models.APIKey, limit int) (bool, error) {
+	created := false
+	err := database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id").Where("id = ?", key.UserID).First(&user).Error; err != nil {
+			return err
+		}
+		if limit > 0 {
+			var count int64
+			if err := tx.Model(&models.APIKey{}).Where("user_id = ?", key.UserID).Count(&count).Error; err != nil {
+				return err
+			}
+			if count >= int64(limit) {
+				return nil
+			}
+		}
+		if err := tx.Create(key).Error; err != nil {
+			return err
+		}
+		created = true
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("创建API密钥失败: %w", err)
+	}
+	return created, nil
+}
+
+// ListByUser 按创建时间倒序列出用户的密钥
+func (r *apiKeyRepository) ListByUser(ctx context.Context, userID uint) ([]*models.APIKey, error) {
+	var keys []*models.APIKey
+	if err := database.Conn(ctx, r.db).Where("user_id = ?", userID).Order("id DESC").Find(&keys).Error; err != nil {
+		return nil, fmt.Errorf("查询API密钥失败: %w", err)
+	}
+	return keys, nil
+}
+
+// GetByHash 按密钥哈希查找，不存在时返回 gorm.ErrRecordNotFound
+func (r *apiKeyRepository) GetByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := database.Conn(ctx, r.db).Where("key_hash = ?", keyHash).First(&key).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// Delete 删除用户的密钥并返回删除前的记录，密钥不存在或不属于该用户时返回 gorm.ErrRecordNotFound
+func (r *apiKeyRepository) Delete(ctx context.Context, userID, id uint) (*models.APIKey, error) {
+	var key models.APIKey
+	err := database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ? AND user_id = ?", id, userID).First(&key).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.APIKey{}, key.ID).Error
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("删除API密钥失败: %w", err)
+	}
+	return &key, nil
+}
+
+// TouchLastUsed 记录最后使用时间和IP，不修改更新时间和版本号
+func (r *apiKeyRepository) TouchLastUsed(ctx context.Context, id uint, ip string, usedAt time.Time) error {
+	return database.Conn(ctx, r.db).Model(&models.APIKey{}).Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{"last_used_at": usedAt, "last_used_ip": ip}).Error
+}
@@ -0,0 +1,35 @@
+package user
+
+import (
+	"context"
+	"time"
+
+	"cloudpan/internal/repository/models"
+)
+
+// InactivityCandidateFilter 长期未登录候选用户的查询条件
+type InactivityCandidateFilter struct {
+	ActiveBefore time.Time // 最后活动时间(最后登录时间，从未登录时为注册时间)不晚于该时间
+	ExemptRoles  []string  // 拥有这些有效角色的用户不是候选
+	Now          time.Time // 判断角色是否过期的当前时间
+}
+
+// InactivityRepository 长期未登录账户处置状态数据仓库接口
+//
+// 候选用户为正常状态或被策略冻结的用户，不包括全部步骤已执行的用户；
+// 执行处置时在同一事务中更新用户并保存状态，用户在此期间重新登录时不执行
+//
+// 使用示例：
+//
+//	repo := NewInactivityRepository(db)
+//	users, err := repo.ListCandidates(ctx, InactivityCandidateFilter{ActiveBefore: before, Now: now}, 0, 100)
+//	applied, err := repo.ApplyStep(ctx, state, map[string]interface{}{"status": "inactive"})
+type InactivityRepository interface {
+	ListCandidates(ctx context.Context, filter InactivityCandidateFilter, afterID uint, limit int) ([]*models.User, error)
+	GetStates(ctx context.Context, userIDs []uint) (map[uint]*models.UserInactivity, error)
+	GetState(ctx context.Context, userID uint) (*models.UserInactivity, error)
+	SaveState(ctx context.Context, state *models.UserInactivity) error
+	ApplyStep(ctx context.Context, state *models.UserInactivity, updates map[string]interface{}) (bool, error)
+	Release(ctx context.Context, userID uint, updates map[string]interface{}) error
+	ListStates(ctx context.Context, offset, limit int) ([]*models.UserInactivity, int64, error)
+}
@@ -0,0 +1,158 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"cloudpan/internal/pkg/database"
+	"cloudpan/internal/repository/models"
+)
+
+// lastActivityColumn 用户最后活动时间，从未登录时为注册时间
+const lastActivityColumn = "COALESCE(users.last_login_at, users.created_at)"
+
+// inactivityRepository 长期未登录账户处置状态数据仓库实现
+type inactivityRepository struct {
+	db *gorm.DB
+}
+
+// NewInactivityRepository 创建长期未登录账户处置状态数据仓库实例
+func NewInactivityRepository(db *gorm.DB) InactivityRepository {
+	return &inactivityRepository{
+		db: db,
+	}
+}
+
+// ListCandidates 按用户ID升序获取ID大于afterID的候选用户
+func (r *inactivityRepository) ListCandidates(ctx context.Context, filter InactivityCandidateFilter, afterID uint, limit int) ([]*models.User, error) {
+	query := database.Conn(ctx, r.db).Model(&models.User{}).
+		Where(lastActivityColumn+" <= ?", filter.ActiveBefore).
+		Where("users.id > ?", afterID).
+		Where("users.status = ? OR (users.status = ? AND EXISTS (SELECT 1 FROM user_inactivities ui WHERE ui.user_id = users.id AND ui.frozen_at IS NOT NULL))",
+			"active", "inactive").
+		Where("NOT EXISTS (SELECT 1 FROM user_inactivities ui WHERE ui.user_id = users.id AND ui.step = ?)", models.InactivityStepDone)
+	if len(filter.ExemptRoles) > 0 {
+		query = query.Where(`NOT EXISTS (SELECT 1 FROM user_roles
+			JOIN roles ON roles.id = user_roles.role_id AND roles.is_active = ? AND roles.deleted_at IS NULL
+			WHERE user_roles.user_id = users.id AND user_roles.is_active = ? AND roles.name IN ?
+			AND (user_roles.expires_at IS NULL OR user_roles.expires_at > ?))`,
+			true, true, filter.ExemptRoles, filter.Now)
+	}
+
+	var users []*models.User
+	if err := query.Order("users.id").Limit(limit).Find(&users).Error; err != nil {
+		return nil, fmt.Errorf("查询长期未登录用户失败: %w", err)
+	}
+	return users, nil
+}
+
+// GetStates 按用户ID批量获取处置状态
+func (r *inactivityRepository) GetStates(ctx context.Context, userIDs []uint) (map[uint]*models.UserInactivity, error) {
+	states := make(map[uint]*models.UserInactivity, len(userIDs))
+	if len(userIDs) == 0 {
+		return states, nil
+	}
+
+	var records []*models.UserInactivity
+	if err := database.Conn(ctx, r.db).Where("user_id IN ?", userIDs).Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("查询长期未登录处置状态失败: %w", err)
+	}
+	for _, record := range records {
+		states[record.UserID] = record
+	}
+	return states, nil
+}
+
+// GetState 获取用户的处置状态，没有时返回nil
+func (r *inactivityRepository) GetState(ctx context.Context, userID uint) (*models.UserInactivity, error) {
+	var state models.UserInactivity
+	err := database.Conn(ctx, r.db).Where("user_id = ?", userID).First(&state).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询长期未登录处置状态失败: %w", err)
+	}
+	return &state, nil
+}
+
+// SaveState 保存处置状态，已有记录时覆盖
+func (r *inactivityRepository) SaveState(ctx context.Context, state *models.UserInactivity) error {
+	return saveInactivityState(database.Conn(ctx, r.db), state)
+}
+
+// ApplyStep 在一个事务中更新用户并保存处置状态
+//
+// 只更新最后活动时间不晚于 state.LastActiveAt 且为正常或禁用状态的用户，用户已重新登录或状态已变化时返回false
+func (r *inactivityRepository) ApplyStep(ctx context.Context, state *models.UserInactivity, updates map[string]interface{}) (bool, error) {
+	applied := false
+	err := database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.User{}).
+			Where("id = ? AND status IN ?", state.UserID, []string{"active", "inactive"}).
+			Where(lastActivityColumn+" <= ?", state.LastActiveAt).
+			UpdateColumns(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		applied = true
+		return saveInactivityState(tx, state)
+	})
+	if err != nil {
+		return false, fmt.Errorf("执行长期未登录处置失败: %w", err)
+	}
+	return applied, nil
+}
+
+// Release 在一个事务中更新用户并删除处置状态，updates为空时只删除状态
+func (r *inactivityRepository) Release(ctx context.Context, userID uint, updates map[string]interface{}) error {
+	err := database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if len(updates) > 0 {
+			if err := tx.Model(&models.User{}).Where("id = ?", userID).UpdateColumns(updates).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.UserInactivity{}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("恢复长期未登录处置失败: %w", err)
+	}
+	return nil
+}
+
+// ListStates 分页获取处置状态并附带用户，按下一步的执行时间升序，未开始提醒的排在最后
+func (r *inactivityRepository) ListStates(ctx context.Context, offset, limit int) ([]*models.UserInactivity, int64, error) {
+	query := database.Conn(ctx, r.db).Model(&models.UserInactivity{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("统计长期未登录处置状态失败: %w", err)
+	}
+
+	var states []*models.UserInactivity
+	err := query.Preload("User").
+		Order("step_due_at IS NULL, step_due_at, user_id").
+		Offset(offset).Limit(limit).
+		Find(&states).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询长期未登录处置状态失败: %w", err)
+	}
+	return states, total, nil
+}
+
+// saveInactivityState 按用户ID写入或覆盖处置状态
+func saveInactivityState(tx *gorm.DB, state *models.UserInactivity) error {
+	return tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"last_active_at", "step", "step_due_at", "warnings_sent", "last_warned_at",
+			"original_quota", "downgraded_at", "frozen_at", "deletion_scheduled_at", "updated_at",
+		}),
+	}).Create(state).Error
+}
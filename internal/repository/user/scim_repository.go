@@ -0,0 +1,69 @@
+package user
+
+import (
+	"context"
+	"time"
+
+	"cloudpan/internal/repository/models"
+)
+
+// SCIMUserQuery 目录同步用户的查询条件，字符串条件为空时不过滤
+type SCIMUserQuery struct {
+	UserName   string // 身份提供方的userName(小写)
+	ExternalID string // 身份提供方的externalId
+	Email      string // 本地账户邮箱(小写)
+	Offset     int
+	Limit      int
+}
+
+// SCIMGroupQuery 目录同步组的查询条件，字符串条件为空时不过滤
+type SCIMGroupQuery struct {
+	DisplayName string // 组名称
+	ExternalID  string // 身份提供方的externalId
+	Offset      int
+	Limit       int
+}
+
+// SCIMRepository 企业目录同步(SCIM)数据仓库接口
+//
+// 提供目录同步令牌、用户关联和组的读写：
+// 1. 令牌：每个身份提供方一个，按哈希查找
+// 2. 用户关联：按(身份提供方, 用户)和(身份提供方, userName)唯一，查询时加载本地账户
+// 3. 组：每个组对应一个团队，创建和删除组时同时创建和删除团队，成员变更后重新统计团队成员数
+//
+// 使用示例：
+//
+//	repo := NewSCIMRepository(db)
+//	token, err := repo.GetTokenByHash(ctx, hash)
+//	link, err := repo.GetUserByUUID(ctx, token.ProviderID, id)
+//	err = repo.AddGroupMembers(ctx, group.TeamID, []uint{link.UserID})
+type SCIMRepository interface {
+	// 令牌
+	SaveToken(ctx context.Context, token *models.SCIMToken) error
+	GetTokenByHash(ctx context.Context, hash string) (*models.SCIMToken, error)
+	GetTokenByProvider(ctx context.Context, providerID uint) (*models.SCIMToken, error)
+	TouchToken(ctx context.Context, id uint, usedAt time.Time) error
+	DeleteToken(ctx context.Context, providerID uint) error
+
+	// 用户关联
+	CreateUser(ctx context.Context, link *models.SCIMUser) error
+	UpdateUser(ctx context.Context, link *models.SCIMUser) error
+	DeleteUser(ctx context.Context, id uint) error
+	GetUserByUUID(ctx context.Context, providerID uint, uuid string) (*models.SCIMUser, error)
+	GetUserByUserID(ctx context.Context, providerID, userID uint) (*models.SCIMUser, error)
+	ListUsers(ctx context.Context, providerID uint, query SCIMUserQuery) ([]*models.SCIMUser, int64, error)
+	ListUsersByUUIDs(ctx context.Context, providerID uint, uuids []string) ([]*models.SCIMUser, error)
+
+	// 组
+	CreateGroup(ctx context.Context, group *models.SCIMGroup) error
+	UpdateGroup(ctx context.Context, group *models.SCIMGroup) error
+	DeleteGroup(ctx context.Context, group *models.SCIMGroup) error
+	GetGroupByUUID(ctx context.Context, providerID uint, uuid string) (*models.SCIMGroup, error)
+	ListGroups(ctx context.Context, providerID uint, query SCIMGroupQuery) ([]*models.SCIMGroup, int64, error)
+
+	// 组成员
+	ListGroupMembers(ctx context.Context, teamID uint) ([]*models.TeamMember, error)
+	AddGroupMembers(ctx context.Context, teamID uint, userIDs []uint) error
+	RemoveGroupMembers(ctx context.Context, teamID uint, userIDs []uint) error
+	RemoveUserFromGroups(ctx context.Context, providerID, userID uint) error
+}
@@ -0,0 +1,366 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"cloudpan/internal/pkg/database"
+	"cloudpan/internal/repository/models"
+)
+
+// scimRepository 企业目录同步数据仓库实现
+type scimRepository struct {
+	db *gorm.DB
+}
+
+// NewSCIMRepository 创建企业目录同步数据仓库实例
+func NewSCIMRepository(db *gorm.DB) SCIMRepository {
+	return &scimRepository{
+		db: db,
+	}
+}
+
+// SaveToken 写入身份提供方的令牌，已有令牌时覆盖(旧令牌立即失效)
+func (r *scimRepository) SaveToken(ctx context.Context, token *models.SCIMToken) error {
+	err := database.Conn(ctx, r.db).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "provider_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"token_hash", "token_prefix", "created_by", "last_used_at", "updated_at"}),
+		}).
+		Create(token).Error
+	if err != nil {
+		return fmt.Errorf("保存目录同步令牌失败: %w", err)
+	}
+	return nil
+}
+
+// GetTokenByHash 按哈希获取令牌，不存在时返回 gorm.ErrRecordNotFound
+func (r *scimRepository) GetTokenByHash(ctx context.Context, hash string) (*models.SCIMToken, error) {
+	var token models.SCIMToken
+	if err := database.Conn(ctx, r.db).Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// GetTokenByProvider 获取身份提供方的令牌，不存在时返回 gorm.ErrRecordNotFound
+func (r *scimRepository) GetTokenByProvider(ctx context.Context, providerID uint) (*models.SCIMToken, error) {
+	var token models.SCIMToken
+	if err := database.Conn(ctx, r.db).Where("provider_id = ?", providerID).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// TouchToken 记录令牌最近使用时间
+func (r *scimRepository) TouchToken(ctx context.Context, id uint, usedAt time.Time) error {
+	err := database.Conn(ctx, r.db).Model(&models.SCIMToken{}).
+		Where("id = ?", id).
+		UpdateColumn("last_used_at", usedAt).Error
+	if err != nil {
+		return fmt.Errorf("记录目录同步令牌使用时间失败: %w", err)
+	}
+	return nil
+}
+
+// DeleteToken 删除身份提供方的令牌
+func (r *scimRepository) DeleteToken(ctx context.Context, providerID uint) error {
+	if err := database.Conn(ctx, r.db).Where("provider_id = ?", providerID).Delete(&models.SCIMToken{}).Error; err != nil {
+		return fmt.Errorf("删除目录同步令牌失败: %w", err)
+	}
+	return nil
+}
+
+// CreateUser 创建用户关联
+func (r *scimRepository) CreateUser(ctx context.Context, link *models.SCIMUser) error {
+	if err := database.Conn(ctx, r.db).Omit("User").Create(link).Error; err != nil {
+		return fmt.Errorf("创建目录同步用户失败: %w", err)
+	}
+	return nil
+}
+
+// UpdateUser 更新用户关联的userName和externalId
+func (r *scimRepository) UpdateUser(ctx context.Context, link *models.SCIMUser) error {
+	err := database.Conn(ctx, r.db).Model(link).
+		Select("user_name", "external_id", "updated_at").
+		Updates(link).Error
+	if err != nil {
+		return fmt.Errorf("更新目录同步用户失败: %w", err)
+	}
+	return nil
+}
+
+// DeleteUser 删除用户关联，本地账户保留
+func (r *scimRepository) DeleteUser(ctx context.Context, id uint) error {
+	if err := database.Conn(ctx, r.db).Delete(&models.SCIMUser{}, id).Error; err != nil {
+		return fmt.Errorf("删除目录同步用户失败: %w", err)
+	}
+	return nil
+}
+
+// GetUserByUUID 按本地账户UUID获取用户关联(含账户)，不存在时返回 gorm.ErrRecordNotFound
+func (r *scimRepository) GetUserByUUID(ctx context.Context, providerID uint, uuid string) (*models.SCIMUser, error) {
+	var link models.SCIMUser
+	err := database.Conn(ctx, r.db).
+		Joins("JOIN users ON users.id = scim_users.user_id").
+		Where("scim_users.provider_id = ? AND users.uuid = ?", providerID, uuid).
+		Preload("User").
+		First(&link).Error
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// GetUserByUserID 按本地用户ID获取用户关联(含账户)，不存在时返回 gorm.ErrRecordNotFound
+func (r *scimRepository) GetUserByUserID(ctx context.Context, providerID, userID uint) (*models.SCIMUser, error) {
+	var link models.SCIMUser
+	err := database.Conn(ctx, r.db).
+		Where("provider_id = ? AND user_id = ?", providerID, userID).
+		Preload("User").
+		First(&link).Error
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// ListUsers 按条件分页查询用户关联(含账户)，按创建顺序排列
+func (r *scimRepository) ListUsers(ctx context.Context, providerID uint, query SCIMUserQuery) ([]*models.SCIMUser, int64, error) {
+	db := database.Conn(ctx, r.db).Model(&models.SCIMUser{}).Where("scim_users.provider_id = ?", providerID)
+	if query.UserName != "" {
+		db = db.Where("scim_users.user_name = ?", query.UserName)
+	}
+	if query.ExternalID != "" {
+		db = db.Where("scim_users.external_id = ?", query.ExternalID)
+	}
+	if query.Email != "" {
+		db = db.Joins("JOIN users ON users.id = scim_users.user_id").Where("users.email = ?", query.Email)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("统计目录同步用户失败: %w", err)
+	}
+	var links []*models.SCIMUser
+	err := db.Preload("User").
+		Order("scim_users.id ASC").
+		Offset(query.Offset).
+		Limit(query.Limit).
+		Find(&links).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询目录同步用户失败: %w", err)
+	}
+	return links, total, nil
+}
+
+// ListUsersByUUIDs 按本地账户UUID批量获取用户关联，不存在的UUID被忽略
+func (r *scimRepository) ListUsersByUUIDs(ctx context.Context, providerID uint, uuids []string) ([]*models.SCIMUser, error) {
+	var links []*models.SCIMUser
+	if len(uuids) == 0 {
+		return links, nil
+	}
+	err := database.Conn(ctx, r.db).
+		Joins("JOIN users ON users.id = scim_users.user_id").
+		Where("scim_users.provider_id = ? AND users.uuid IN ?", providerID, uuids).
+		Preload("User").
+		Find(&links).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询目录同步用户失败: %w", err)
+	}
+	return links, nil
+}
+
+// CreateGroup 创建组及其对应的团队，group.Team 为要创建的团队
+func (r *scimRepository) CreateGroup(ctx context.Context, group *models.SCIMGroup) error {
+	return database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(&group.Team).Error; err != nil {
+			return fmt.Errorf("创建团队失败: %w", err)
+		}
+		group.TeamID = group.Team.ID
+		if err := tx.Omit("Team").Create(group).Error; err != nil {
+			return fmt.Errorf("创建目录同步组失败: %w", err)
+		}
+		return recountTeamMembers(tx, group.TeamID)
+	})
+}
+
+// UpdateGroup 更新组名称和externalId，团队名称随之更新
+func (r *scimRepository) UpdateGroup(ctx context.Context, group *models.SCIMGroup) error {
+	return database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(group).
+			Select("display_name", "external_id", "updated_at").
+			Updates(group).Error
+		if err != nil {
+			return fmt.Errorf("更新目录同步组失败: %w", err)
+		}
+		err = tx.Model(&models.Team{}).
+			Where("id = ?", group.TeamID).
+			Update("name", group.DisplayName).Error
+		if err != nil {
+			return fmt.Errorf("更新团队名称失败: %w", err)
+		}
+		return nil
+	})
+}
+
+// DeleteGroup 删除组，移除全部团队成员并删除团队
+func (r *scimRepository) DeleteGroup(ctx context.Context, group *models.SCIMGroup) error {
+	return database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("team_id = ?", group.TeamID).Delete(&models.TeamMember{}).Error; err != nil {
+			return fmt.Errorf("移除团队成员失败: %w", err)
+		}
+		if err := tx.Delete(&models.SCIMGroup{}, group.ID).Error; err != nil {
+			return fmt.Errorf("删除目录同步组失败: %w", err)
+		}
+		if err := tx.Delete(&models.Team{}, group.TeamID).Error; err != nil {
+			return fmt.Errorf("删除团队失败: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetGroupByUUID 按团队UUID获取组(含团队)，不存在时返回 gorm.ErrRecordNotFound
+func (r *scimRepository) GetGroupByUUID(ctx context.Context, providerID uint, uuid string) (*models.SCIMGroup, error) {
+	var group models.SCIMGroup
+	err := database.Conn(ctx, r.db).
+		Joins("JOIN teams ON teams.id = scim_groups.team_id AND teams.deleted_at IS NULL").
+		Where("scim_groups.provider_id = ? AND teams.uuid = ?", providerID, uuid).
+		Preload("Team").
+		First(&group).Error
+	if err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// ListGroups 按条件分页查询组(含团队)，按创建顺序排列
+func (r *scimRepository) ListGroups(ctx context.Context, providerID uint, query SCIMGroupQuery) ([]*models.SCIMGroup, int64, error) {
+	db := database.Conn(ctx, r.db).Model(&models.SCIMGroup{}).Where("provider_id = ?", providerID)
+	if query.DisplayName != "" {
+		db = db.Where("display_name = ?", query.DisplayName)
+	}
+	if query.ExternalID != "" {
+		db = db.Where("external_id = ?", query.ExternalID)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("统计目录同步组失败: %w", err)
+	}
+	var groups []*models.SCIMGroup
+	err := db.Preload("Team").
+		Order("id ASC").
+		Offset(query.Offset).
+		Limit(query.Limit).
+		Find(&groups).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询目录同步组失败: %w", err)
+	}
+	return groups, total, nil
+}
+
+// ListGroupMembers 列出团队成员(含账户)
+func (r *scimRepository) ListGroupMembers(ctx context.Context, teamID uint) ([]*models.TeamMember, error) {
+	var members []*models.TeamMember
+	err := database.Conn(ctx, r.db).
+		Where("team_id = ?", teamID).
+		Preload("User").
+		Order("id ASC").
+		Find(&members).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询团队成员失败: %w", err)
+	}
+	return members, nil
+}
+
+// AddGroupMembers 将用户加入团队，已是成员的用户被跳过
+func (r *scimRepository) AddGroupMembers(ctx context.Context, teamID uint, userIDs []uint) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	return database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		var existing []uint
+		err := tx.Model(&models.TeamMember{}).
+			Where("team_id = ? AND user_id IN ?", teamID, userIDs).
+			Pluck("user_id", &existing).Error
+		if err != nil {
+			return fmt.Errorf("查询团队成员失败: %w", err)
+		}
+		joined := make(map[uint]bool, len(existing))
+		for _, id := range existing {
+			joined[id] = true
+		}
+
+		for _, userID := range userIDs {
+			if joined[userID] {
+				continue
+			}
+			joined[userID] = true
+			member := &models.TeamMember{TeamID: teamID, UserID: userID, Role: "member", Status: "active"}
+			if err := tx.Omit(clause.Associations).Create(member).Error; err != nil {
+				return fmt.Errorf("添加团队成员失败: %w", err)
+			}
+		}
+		return recountTeamMembers(tx, teamID)
+	})
+}
+
+// RemoveGroupMembers 将用户移出团队
+func (r *scimRepository) RemoveGroupMembers(ctx context.Context, teamID uint, userIDs []uint) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	return database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		err := tx.Unscoped().
+			Where("team_id = ? AND user_id IN ?", teamID, userIDs).
+			Delete(&models.TeamMember{}).Error
+		if err != nil {
+			return fmt.Errorf("移除团队成员失败: %w", err)
+		}
+		return recountTeamMembers(tx, teamID)
+	})
+}
+
+// RemoveUserFromGroups 将用户移出身份提供方同步的全部团队
+func (r *scimRepository) RemoveUserFromGroups(ctx context.Context, providerID, userID uint) error {
+	return database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		var teamIDs []uint
+		err := tx.Model(&models.SCIMGroup{}).
+			Where("provider_id = ?", providerID).
+			Where("team_id IN (?)", tx.Model(&models.TeamMember{}).Select("team_id").Where("user_id = ?", userID)).
+			Pluck("team_id", &teamIDs).Error
+		if err != nil {
+			return fmt.Errorf("查询用户所在团队失败: %w", err)
+		}
+		if len(teamIDs) == 0 {
+			return nil
+		}
+
+		err = tx.Unscoped().
+			Where("user_id = ? AND team_id IN ?", userID, teamIDs).
+			Delete(&models.TeamMember{}).Error
+		if err != nil {
+			return fmt.Errorf("移除团队成员失败: %w", err)
+		}
+		for _, teamID := range teamIDs {
+			if err := recountTeamMembers(tx, teamID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// recountTeamMembers 按成员记录重新统计团队成员数
+func recountTeamMembers(tx *gorm.DB, teamID uint) error {
+	count := tx.Model(&models.TeamMember{}).Select("COUNT(*)").Where("team_id = ?", teamID)
+	if err := tx.Model(&models.Team{}).Where("id = ?", teamID).Update("member_count", count).Error; err != nil {
+		return fmt.Errorf("统计团队成员数失败: %w", err)
+	}
+	return nil
+}
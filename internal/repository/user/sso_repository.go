@@ -0,0 +1,42 @@
+package user
+
+import (
+	"context"
+	"time"
+
+	"cloudpan/internal/repository/models"
+)
+
+// SSORepository 企业单点登录数据仓库接口
+//
+// 提供身份提供方、邮箱域名和身份关联的读写：
+// 1. 身份提供方：按租户唯一，删除时一并释放认领的域名
+// 2. 邮箱域名：全局唯一，记录DNS验证时间
+// 3. 身份关联：按(身份提供方, subject)唯一，登录时更新邮箱、角色和登录时间
+//
+// 使用示例：
+//
+//	repo := NewSSORepository(db)
+//	domain, err := repo.GetDomain(ctx, "example.com")
+//	provider, err := repo.GetProvider(ctx, domain.ProviderID)
+//	err = repo.SaveIdentity(ctx, &models.SSOIdentity{ProviderID: provider.ID, Subject: sub, UserID: userID})
+type SSORepository interface {
+	// 身份提供方
+	CreateProvider(ctx context.Context, provider *models.SSOProvider) error
+	UpdateProvider(ctx context.Context, provider *models.SSOProvider) error
+	DeleteProvider(ctx context.Context, id uint) error
+	GetProvider(ctx context.Context, id uint) (*models.SSOProvider, error)
+	GetProviderByTenant(ctx context.Context, tenant string) (*models.SSOProvider, error)
+	ListProviders(ctx context.Context) ([]*models.SSOProvider, error)
+
+	// 邮箱域名
+	CreateDomain(ctx context.Context, domain *models.SSODomain) error
+	GetDomain(ctx context.Context, domain string) (*models.SSODomain, error)
+	GetDomainByID(ctx context.Context, id uint) (*models.SSODomain, error)
+	MarkDomainVerified(ctx context.Context, id uint, verifiedAt time.Time) error
+	DeleteDomain(ctx context.Context, id uint) error
+
+	// 身份关联
+	GetIdentity(ctx context.Context, providerID uint, subject string) (*models.SSOIdentity, error)
+	SaveIdentity(ctx context.Context, identity *models.SSOIdentity) error
+}
@@ -0,0 +1,156 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"cloudpan/internal/pkg/database"
+	"cloudpan/internal/repository/models"
+)
+
+// ssoRepository 企业单点登录数据仓库实现
+type ssoRepository struct {
+	db *gorm.DB
+}
+
+// NewSSORepository 创建企业单点登录数据仓库实例
+func NewSSORepository(db *gorm.DB) SSORepository {
+	return &ssoRepository{
+		db: db,
+	}
+}
+
+// CreateProvider 创建身份提供方
+func (r *ssoRepository) CreateProvider(ctx context.Context, provider *models.SSOProvider) error {
+	if err := database.Conn(ctx, r.db).Omit("Domains").Create(provider).Error; err != nil {
+		return fmt.Errorf("创建身份提供方失败: %w", err)
+	}
+	return nil
+}
+
+// UpdateProvider 更新身份提供方的可编辑字段，租户标识不可修改
+func (r *ssoRepository) UpdateProvider(ctx context.Context, provider *models.SSOProvider) error {
+	err := database.Conn(ctx, r.db).Model(provider).
+		Select("name", "issuer", "client_id", "client_secret", "scopes", "groups_claim",
+			"role_mappings", "default_role", "jit_enabled", "enforce_sso", "is_active", "updated_at").
+		Updates(provider).Error
+	if err != nil {
+		return fmt.Errorf("更新身份提供方失败: %w", err)
+	}
+	return nil
+}
+
+// DeleteProvider 删除身份提供方并释放其认领的域名，身份关联保留用于审计
+func (r *ssoRepository) DeleteProvider(ctx context.Context, id uint) error {
+	return database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("provider_id = ?", id).Delete(&models.SSODomain{}).Error; err != nil {
+			return fmt.Errorf("删除身份提供方域名失败: %w", err)
+		}
+		if err := tx.Delete(&models.SSOProvider{}, id).Error; err != nil {
+			return fmt.Errorf("删除身份提供方失败: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetProvider 获取身份提供方(含域名)，不存在时返回 gorm.ErrRecordNotFound
+func (r *ssoRepository) GetProvider(ctx context.Context, id uint) (*models.SSOProvider, error) {
+	var provider models.SSOProvider
+	if err := database.Conn(ctx, r.db).Preload("Domains").First(&provider, id).Error; err != nil {
+		return nil, err
+	}
+	return &provider, nil
+}
+
+// GetProviderByTenant 按租户获取身份提供方(含域名)，不存在时返回 gorm.ErrRecordNotFound
+func (r *ssoRepository) GetProviderByTenant(ctx context.Context, tenant string) (*models.SSOProvider, error) {
+	var provider models.SSOProvider
+	if err := database.Conn(ctx, r.db).Preload("Domains").Where("tenant = ?", tenant).First(&provider).Error; err != nil {
+		return nil, err
+	}
+	return &provider, nil
+}
+
+// ListProviders 按租户排序列出全部身份提供方(含域名)
+func (r *ssoRepository) ListProviders(ctx context.Context) ([]*models.SSOProvider, error) {
+	var providers []*models.SSOProvider
+	if err := database.Conn(ctx, r.db).Preload("Domains").Order("tenant ASC").Find(&providers).Error; err != nil {
+		return nil, fmt.Errorf("查询身份提供方失败: %w", err)
+	}
+	return providers, nil
+}
+
+// CreateDomain 登记邮箱域名
+func (r *ssoRepository) CreateDomain(ctx context.Context, domain *models.SSODomain) error {
+	if err := database.Conn(ctx, r.db).Create(domain).Error; err != nil {
+		return fmt.Errorf("登记邮箱域名失败: %w", err)
+	}
+	return nil
+}
+
+// GetDomain 按域名获取登记记录，不存在时返回 gorm.ErrRecordNotFound
+func (r *ssoRepository) GetDomain(ctx context.Context, domain string) (*models.SSODomain, error) {
+	var record models.SSODomain
+	if err := database.Conn(ctx, r.db).Where("domain = ?", domain).First(&record).Error; err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// GetDomainByID 按ID获取域名登记记录，不存在时返回 gorm.ErrRecordNotFound
+func (r *ssoRepository) GetDomainByID(ctx context.Context, id uint) (*models.SSODomain, error) {
+	var record models.SSODomain
+	if err := database.Conn(ctx, r.db).First(&record, id).Error; err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// MarkDomainVerified 记录域名验证时间
+func (r *ssoRepository) MarkDomainVerified(ctx context.Context, id uint, verifiedAt time.Time) error {
+	err := database.Conn(ctx, r.db).Model(&models.SSODomain{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"verified_at": verifiedAt, "updated_at": time.Now()}).Error
+	if err != nil {
+		return fmt.Errorf("记录域名验证失败: %w", err)
+	}
+	return nil
+}
+
+// DeleteDomain 删除域名登记
+func (r *ssoRepository) DeleteDomain(ctx context.Context, id uint) error {
+	if err := database.Conn(ctx, r.db).Delete(&models.SSODomain{}, id).Error; err != nil {
+		return fmt.Errorf("删除邮箱域名失败: %w", err)
+	}
+	return nil
+}
+
+// GetIdentity 获取身份关联，不存在时返回 gorm.ErrRecordNotFound
+func (r *ssoRepository) GetIdentity(ctx context.Context, providerID uint, subject string) (*models.SSOIdentity, error) {
+	var identity models.SSOIdentity
+	err := database.Conn(ctx, r.db).
+		Where("provider_id = ? AND subject = ?", providerID, subject).
+		First(&identity).Error
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// SaveIdentity 写入身份关联，已存在时更新邮箱、角色和登录时间(不改变关联的用户)
+func (r *ssoRepository) SaveIdentity(ctx context.Context, identity *models.SSOIdentity) error {
+	err := database.Conn(ctx, r.db).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "provider_id"}, {Name: "subject"}},
+			DoUpdates: clause.AssignmentColumns([]string{"email", "role", "last_login_at", "updated_at"}),
+		}).
+		Create(identity).Error
+	if err != nil {
+		return fmt.Errorf("保存单点登录身份关联失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package user
+
+import (
+	"context"
+	"time"
+
+	"cloudpan/internal/repository/models"
+)
+
+// StorageReservationRepository 上传存储空间预留数据仓库接口
+//
+// 预留和已用空间的变更都在锁定用户记录的事务中进行，并发上传不会合计超出配额：
+// 1. 预留：锁定用户记录，合计其他未过期的预留后调用 check 检查配额，check 返回错误时不登记
+// 2. 提交：删除预留并累计已用空间，预留已过期被清理时仍累计实际大小
+// 3. 释放：删除预留，上传失败或过期时调用
+//
+// 使用示例：
+//
+//	repo := NewStorageReservationRepository(db)
+//	err := repo.Reserve(ctx, &models.StorageReservation{UserID: userID, UploadID: uploadID, Size: size, ExpiresAt: expiresAt}, time.Now(), check)
+//	err = repo.Commit(ctx, userID, uploadID, actualSize)
+type StorageReservationRepository interface {
+	Reserve(ctx context.Context, reservation *models.StorageReservation, now time.Time, check func(user *models.User, reserved int64) error) error
+	Commit(ctx context.Context, userID uint, uploadID string, size int64) error
+	Release(ctx context.Context, uploadID string) (bool, error)
+	SumActive(ctx context.Context, userID uint, now time.Time) (int64, error)
+	DeleteExpired(ctx context.Context, now time.Time, limit int) (int64, error)
+}
@@ -0,0 +1,110 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"cloudpan/internal/pkg/database"
+	"cloudpan/internal/repository/models"
+)
+
+// storageReservationRepository 上传存储空间预留数据仓库实现
+type storageReservationRepository struct {
+	db *gorm.DB
+}
+
+// NewStorageReservationRepository 创建上传存储空间预留数据仓库实例
+func NewStorageReservationRepository(db *gorm.DB) StorageReservationRepository {
+	return &storageReservationRepository{
+		db: db,
+	}
+}
+
+// Reserve 在锁定的用户记录上检查配额并登记预留，同一上传重复预留时更新大小和过期时间
+//
+// 用户不存在时返回 gorm.ErrRecordNotFound；check 返回的错误原样返回
+func (r *storageReservationRepository) Reserve(ctx context.Context, reservation *models.StorageReservation, now time.Time, check func(user *models.User, reserved int64) error) error {
+	return database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", reservation.UserID).First(&user).Error; err != nil {
+			return err
+		}
+
+		var reserved int64
+		if err := tx.Model(&models.StorageReservation{}).
+			Where("user_id = ? AND upload_id <> ? AND expires_at > ?", reservation.UserID, reservation.UploadID, now).
+			Select("COALESCE(SUM(size), 0)").Scan(&reserved).Error; err != nil {
+			return fmt.Errorf("合计存储预留失败: %w", err)
+		}
+		if err := check(&user, reserved); err != nil {
+			return err
+		}
+
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "upload_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"size", "expires_at", "updated_at"}),
+		}).Create(reservation).Error; err != nil {
+			return fmt.Errorf("登记存储预留失败: %w", err)
+		}
+		return nil
+	})
+}
+
+// Commit 删除预留并累计用户已用空间
+func (r *storageReservationRepository) Commit(ctx context.Context, userID uint, uploadID string, size int64) error {
+	err := database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("upload_id = ?", uploadID).Delete(&models.StorageReservation{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.User{}).Where("id = ?", userID).
+			UpdateColumn("storage_used", gorm.Expr("storage_used + ?", size)).Error
+	})
+	if err != nil {
+		return fmt.Errorf("提交存储预留失败: %w", err)
+	}
+	return nil
+}
+
+// Release 删除预留，预留不存在时返回false
+func (r *storageReservationRepository) Release(ctx context.Context, uploadID string) (bool, error) {
+	result := database.Conn(ctx, r.db).Where("upload_id = ?", uploadID).Delete(&models.StorageReservation{})
+	if result.Error != nil {
+		return false, fmt.Errorf("释放存储预留失败: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// SumActive 合计用户未过期的预留大小
+func (r *storageReservationRepository) SumActive(ctx context.Context, userID uint, now time.Time) (int64, error) {
+	var reserved int64
+	err := database.Conn(ctx, r.db).Model(&models.StorageReservation{}).
+		Where("user_id = ? AND expires_at > ?", userID, now).
+		Select("COALESCE(SUM(size), 0)").Scan(&reserved).Error
+	if err != nil {
+		return 0, fmt.Errorf("合计存储预留失败: %w", err)
+	}
+	return reserved, nil
+}
+
+// DeleteExpired 删除最多limit条已过期的预留，返回删除的条数
+func (r *storageReservationRepository) DeleteExpired(ctx context.Context, now time.Time, limit int) (int64, error) {
+	var ids []uint
+	if err := database.Conn(ctx, r.db).Model(&models.StorageReservation{}).
+		Where("expires_at <= ?", now).
+		Order("id").Limit(limit).Pluck("id", &ids).Error; err != nil {
+		return 0, fmt.Errorf("查询过期存储预留失败: %w", err)
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	result := database.Conn(ctx, r.db).Where("id IN ?", ids).Delete(&models.StorageReservation{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("删除过期存储预留失败: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
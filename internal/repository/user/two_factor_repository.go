@@ -0,0 +1,31 @@
+package user
+
+import (
+	"context"
+	"time"
+
+	"cloudpan/internal/repository/models"
+)
+
+// TwoFactorRepository 用户两步验证登记数据仓库接口
+//
+// 提供TOTP登记记录的读写，启用和删除时同步更新用户表的 mfa_enabled 字段：
+// 1. 登记：写入或覆盖未启用的登记记录
+// 2. 启用/删除：切换两步验证状态
+// 3. 防重放：只接受晚于最近一次通过验证的时间步
+// 4. 备用码：以比较并交换的方式更新，避免并发使用同一备用码
+//
+// 使用示例：
+//
+//	repo := NewTwoFactorRepository(db)
+//	err := repo.SavePending(ctx, &models.UserTwoFactor{UserID: userID, Secret: secret})
+//	ok, err := repo.Enable(ctx, userID, backupCodes, step, time.Now())
+//	ok, err = repo.UseStep(ctx, userID, step)
+type TwoFactorRepository interface {
+	GetByUserID(ctx context.Context, userID uint) (*models.UserTwoFactor, error)
+	SavePending(ctx context.Context, record *models.UserTwoFactor) error
+	Enable(ctx context.Context, userID uint, backupCodes string, step int64, now time.Time) (bool, error)
+	Delete(ctx context.Context, userID uint) error
+	UseStep(ctx context.Context, userID uint, step int64) (bool, error)
+	ReplaceBackupCodes(ctx context.Context, userID uint, oldCodes, newCodes string) (bool, error)
+}
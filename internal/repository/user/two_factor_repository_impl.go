@@ -0,0 +1,118 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"cloudpan/internal/pkg/database"
+	"cloudpan/internal/repository/models"
+)
+
+// twoFactorRepository 用户两步验证登记数据仓库实现
+type twoFactorRepository struct {
+	db *gorm.DB
+}
+
+// NewTwoFactorRepository 创建用户两步验证登记数据仓库实例
+func NewTwoFactorRepository(db *gorm.DB) TwoFactorRepository {
+	return &twoFactorRepository{
+		db: db,
+	}
+}
+
+// GetByUserID 获取用户的登记记录，不存在时返回 gorm.ErrRecordNotFound
+func (r *twoFactorRepository) GetByUserID(ctx context.Context, userID uint) (*models.UserTwoFactor, error) {
+	var record models.UserTwoFactor
+	if err := database.Conn(ctx, r.db).Where("user_id = ?", userID).First(&record).Error; err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// SavePending 写入未启用的登记记录，已有记录时覆盖密钥并清空状态
+func (r *twoFactorRepository) SavePending(ctx context.Context, record *models.UserTwoFactor) error {
+	record.Enabled = false
+	record.EnabledAt = nil
+	record.LastUsedStep = 0
+	record.BackupCodes = ""
+
+	err := database.Conn(ctx, r.db).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"secret", "enabled", "enabled_at", "last_used_step", "backup_codes", "updated_at"}),
+		}).
+		Create(record).Error
+	if err != nil {
+		return fmt.Errorf("保存两步验证登记失败: %w", err)
+	}
+	return nil
+}
+
+// Enable 启用未启用的登记记录并同步用户表，记录不存在或已启用时返回false
+func (r *twoFactorRepository) Enable(ctx context.Context, userID uint, backupCodes string, step int64, now time.Time) (bool, error) {
+	enabled := false
+	err := database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.UserTwoFactor{}).
+			Where("user_id = ? AND enabled = ?", userID, false).
+			UpdateColumns(map[string]interface{}{
+				"enabled":        true,
+				"enabled_at":     now,
+				"last_used_step": step,
+				"backup_codes":   backupCodes,
+				"updated_at":     now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		enabled = true
+		return tx.Model(&models.User{}).Where("id = ?", userID).
+			UpdateColumns(map[string]interface{}{"mfa_enabled": true, "mfa_type": "totp"}).Error
+	})
+	if err != nil {
+		return false, fmt.Errorf("启用两步验证失败: %w", err)
+	}
+	return enabled, nil
+}
+
+// Delete 删除登记记录并同步用户表
+func (r *twoFactorRepository) Delete(ctx context.Context, userID uint) error {
+	err := database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.UserTwoFactor{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.User{}).Where("id = ?", userID).UpdateColumn("mfa_enabled", false).Error
+	})
+	if err != nil {
+		return fmt.Errorf("关闭两步验证失败: %w", err)
+	}
+	return nil
+}
+
+// UseStep 记录通过验证的时间步，时间步不晚于已记录的时间步时返回false
+func (r *twoFactorRepository) UseStep(ctx context.Context, userID uint, step int64) (bool, error) {
+	result := database.Conn(ctx, r.db).Model(&models.UserTwoFactor{}).
+		Where("user_id = ? AND enabled = ? AND last_used_step < ?", userID, true, step).
+		UpdateColumn("last_used_step", step)
+	if result.Error != nil {
+		return false, fmt.Errorf("记录验证时间步失败: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// ReplaceBackupCodes 备用码仍为oldCodes时替换为newCodes，已被并发修改时返回false
+func (r *twoFactorRepository) ReplaceBackupCodes(ctx context.Context, userID uint, oldCodes, newCodes string) (bool, error) {
+	result := database.Conn(ctx, r.db).Model(&models.UserTwoFactor{}).
+		Where("user_id = ? AND enabled = ? AND backup_codes = ?", userID, true, oldCodes).
+		UpdateColumn("backup_codes", newCodes)
+	if result.Error != nil {
+		return false, fmt.Errorf("更新备用码失败: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
@@ -2,6 +2,7 @@ package user
 
 import (
 	"context"
+	"time"
 
 	"cloudpan/internal/repository/models"
 )
@@ -13,6 +14,9 @@ import (
 // 2. 用户查询和检索
 // 3. 用户验证和校验
 // 4. 用户偏好设置管理
+// 5. 强制重置密码标记
+// 6. 用户角色查询
+// 7. 过期会话清理
 //
 // 使用示例：
 //
@@ -54,4 +58,15 @@ type UserRepository interface {
 	// 统计信息
 	GetTotalUsersCount(ctx context.Context) (int64, error)
 	GetUsersByStatus(ctx context.Context, status string, limit, offset int) ([]*models.User, int64, error)
+
+	// 强制重置密码
+	ListByIDs(ctx context.Context, ids []uint) ([]*models.User, error)
+	MarkPasswordResetRequired(ctx context.Context, ids []uint, requestedAt time.Time) error
+
+	// 用户角色
+	ListActiveRoleNames(ctx context.Context, userID uint) ([]string, error)
+	ListActiveUserIDsByRole(ctx context.Context, roleName string) ([]uint, error)
+
+	// 会话清理
+	DeleteExpiredSessions(ctx context.Context, before time.Time, limit int) (int64, error)
 }
@@ -3,10 +3,12 @@ package user
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 
+	"cloudpan/internal/pkg/database"
 	"cloudpan/internal/repository/models"
 )
 
@@ -28,7 +30,7 @@ func (r *userRepository) Create(ctx context.Context, user *models.User) error {
 		return fmt.Errorf("用户数据不能为空")
 	}
 
-	return r.db.WithContext(ctx).Create(user).Error
+	return database.Conn(ctx, r.db).Create(user).Error
 }
 
 // GetByID 根据ID获取用户
@@ -38,7 +40,7 @@ func (r *userRepository) GetByID(ctx context.Context, id uint) (*models.User, er
 	}
 
 	var user models.User
-	err := r.db.WithContext(ctx).First(&user, id).Error
+	err := database.Conn(ctx, r.db).First(&user, id).Error
 	if err != nil {
 		return nil, err
 	}
@@ -53,7 +55,7 @@ func (r *userRepository) GetByUUID(ctx context.Context, uuid string) (*models.Us
 	}
 
 	var user models.User
-	err := r.db.WithContext(ctx).Where("uuid = ?", uuid).First(&user).Error
+	err := database.Conn(ctx, r.db).Where("uuid = ?", uuid).First(&user).Error
 	if err != nil {
 		return nil, err
 	}
@@ -68,7 +70,7 @@ func (r *userRepository) GetByEmail(ctx context.Context, email string) (*models.
 	}
 
 	var user models.User
-	err := r.db.WithContext(ctx).Where("email = ?", email).First(&user).Error
+	err := database.Conn(ctx, r.db).Where("email = ?", email).First(&user).Error
 	if err != nil {
 		return nil, err
 	}
@@ -83,7 +85,7 @@ func (r *userRepository) GetByUsername(ctx context.Context, username string) (*m
 	}
 
 	var user models.User
-	err := r.db.WithContext(ctx).Where("username = ?", username).First(&user).Error
+	err := database.Conn(ctx, r.db).Where("username = ?", username).First(&user).Error
 	if err != nil {
 		return nil, err
 	}
@@ -97,7 +99,7 @@ func (r *userRepository) Update(ctx context.Context, user *models.User) error {
 		return fmt.Errorf("用户数据不能为空")
 	}
 
-	return r.db.WithContext(ctx).Save(user).Error
+	return database.Conn(ctx, r.db).Save(user).Error
 }
 
 // Delete 删除用户（软删除）
@@ -106,7 +108,7 @@ func (r *userRepository) Delete(ctx context.Context, id uint) error {
 		return fmt.Errorf("用户ID不能为空")
 	}
 
-	return r.db.WithContext(ctx).Delete(&models.User{}, id).Error
+	return database.Conn(ctx, r.db).Delete(&models.User{}, id).Error
 }
 
 // ExistsByEmail 检查邮箱是否存在
@@ -116,7 +118,7 @@ func (r *userRepository) ExistsByEmail(ctx context.Context, email string) (bool,
 	}
 
 	var count int64
-	err := r.db.WithContext(ctx).Model(&models.User{}).Where("email = ?", email).Count(&count).Error
+	err := database.Conn(ctx, r.db).Model(&models.User{}).Where("email = ?", email).Count(&count).Error
 	if err != nil {
 		return false, err
 	}
@@ -131,7 +133,7 @@ func (r *userRepository) ExistsByUsername(ctx context.Context, username string)
 	}
 
 	var count int64
-	err := r.db.WithContext(ctx).Model(&models.User{}).Where("username = ?", username).Count(&count).Error
+	err := database.Conn(ctx, r.db).Model(&models.User{}).Where("username = ?", username).Count(&count).Error
 	if err != nil {
 		return false, err
 	}
@@ -146,7 +148,7 @@ func (r *userRepository) ExistsByID(ctx context.Context, id uint) (bool, error)
 	}
 
 	var count int64
-	err := r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).Count(&count).Error
+	err := database.Conn(ctx, r.db).Model(&models.User{}).Where("id = ?", id).Count(&count).Error
 	if err != nil {
 		return false, err
 	}
@@ -166,12 +168,12 @@ func (r *userRepository) List(ctx context.Context, limit, offset int) ([]*models
 	var total int64
 
 	// 获取总数
-	if err := r.db.WithContext(ctx).Model(&models.User{}).Count(&total).Error; err != nil {
+	if err := database.Conn(ctx, r.db).Model(&models.User{}).Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
 	// 获取分页数据
-	err := r.db.WithContext(ctx).
+	err := database.Conn(ctx, r.db).
 		Limit(limit).
 		Offset(offset).
 		Order("created_at DESC").
@@ -189,7 +191,7 @@ func (r *userRepository) Search(ctx context.Context, keyword string, limit, offs
 	var users []*models.User
 	var total int64
 
-	query := r.db.WithContext(ctx).Model(&models.User{})
+	query := database.Conn(ctx, r.db).Model(&models.User{})
 
 	// 构建搜索条件
 	if keyword != "" {
@@ -220,7 +222,7 @@ func (r *userRepository) Search(ctx context.Context, keyword string, limit, offs
 // GetActiveUsersCount 获取活跃用户数量
 func (r *userRepository) GetActiveUsersCount(ctx context.Context) (int64, error) {
 	var count int64
-	err := r.db.WithContext(ctx).Model(&models.User{}).
+	err := database.Conn(ctx, r.db).Model(&models.User{}).
 		Where("status = ?", "active").
 		Count(&count).Error
 	if err != nil {
@@ -236,7 +238,7 @@ func (r *userRepository) UpdateStorageUsed(ctx context.Context, userID uint, siz
 		return fmt.Errorf("用户ID不能为空")
 	}
 
-	return r.db.WithContext(ctx).Model(&models.User{}).
+	return database.Conn(ctx, r.db).Model(&models.User{}).
 		Where("id = ?", userID).
 		UpdateColumn("storage_used", gorm.Expr("storage_used + ?", size)).Error
 }
@@ -249,7 +251,7 @@ func (r *userRepository) GetUserFileCount(ctx context.Context, userID uint) (int
 
 	var count int64
 	// 注意：这里假设有files表，实际实现时需要根据文件模型调整
-	err := r.db.WithContext(ctx).Table("files").
+	err := database.Conn(ctx, r.db).Table("files").
 		Where("user_id = ? AND deleted_at IS NULL", userID).
 		Count(&count).Error
 	if err != nil {
@@ -267,7 +269,7 @@ func (r *userRepository) GetUserPreferences(ctx context.Context, userID uint, ca
 	}
 
 	var preferences []*models.UserPreference
-	query := r.db.WithContext(ctx).Where("user_id = ?", userID)
+	query := database.Conn(ctx, r.db).Where("user_id = ?", userID)
 
 	if category != "" {
 		query = query.Where("category = ?", category)
@@ -296,7 +298,7 @@ func (r *userRepository) SetUserPreference(ctx context.Context, userID uint, cat
 	}
 
 	// 使用 ON DUPLICATE KEY UPDATE 或 UPSERT 逻辑
-	return r.db.WithContext(ctx).
+	return database.Conn(ctx, r.db).
 		Where("user_id = ? AND category = ? AND key = ?", userID, category, key).
 		Assign(models.UserPreference{Value: &value}).
 		FirstOrCreate(preference).Error
@@ -308,7 +310,7 @@ func (r *userRepository) DeleteUserPreference(ctx context.Context, userID uint,
 		return fmt.Errorf("用户ID、分类和键不能为空")
 	}
 
-	return r.db.WithContext(ctx).
+	return database.Conn(ctx, r.db).
 		Where("user_id = ? AND category = ? AND key = ?", userID, category, key).
 		Delete(&models.UserPreference{}).Error
 }
@@ -316,7 +318,7 @@ func (r *userRepository) DeleteUserPreference(ctx context.Context, userID uint,
 // GetTotalUsersCount 获取用户总数
 func (r *userRepository) GetTotalUsersCount(ctx context.Context) (int64, error) {
 	var count int64
-	err := r.db.WithContext(ctx).Model(&models.User{}).Count(&count).Error
+	err := database.Conn(ctx, r.db).Model(&models.User{}).Count(&count).Error
 	if err != nil {
 		return 0, err
 	}
@@ -329,7 +331,7 @@ func (r *userRepository) GetUsersByStatus(ctx context.Context, status string, li
 	var users []*models.User
 	var total int64
 
-	query := r.db.WithContext(ctx).Model(&models.User{})
+	query := database.Conn(ctx, r.db).Model(&models.User{})
 
 	if status != "" {
 		query = query.Where("status = ?", status)
@@ -353,3 +355,98 @@ func (r *userRepository) GetUsersByStatus(ctx context.Context, status string, li
 
 	return users, total, nil
 }
+
+// ListByIDs 批量获取用户，不存在的ID不返回
+//
+// 同时加载界面语言偏好，批量发送邮件时按 User.PreferredLanguage 选择模板语言
+func (r *userRepository) ListByIDs(ctx context.Context, ids []uint) ([]*models.User, error) {
+	if len(ids) == 0 {
+		return []*models.User{}, nil
+	}
+
+	var users []*models.User
+	err := database.Conn(ctx, r.db).
+		Preload("Preferences", "category = ? AND `key` = ?", models.PreferenceCategoryUI, models.PreferenceKeyLanguage).
+		Where("id IN ?", ids).
+		Find(&users).Error
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// MarkPasswordResetRequired 批量标记用户必须重置密码
+//
+// 使用UpdateColumns避免触发版本号和更新时间变更
+func (r *userRepository) MarkPasswordResetRequired(ctx context.Context, ids []uint, requestedAt time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	return database.Conn(ctx, r.db).Model(&models.User{}).
+		Where("id IN ?", ids).
+		UpdateColumns(map[string]interface{}{
+			"password_reset_required":     true,
+			"password_reset_requested_at": requestedAt,
+		}).Error
+}
+
+// ListActiveRoleNames 获取用户当前有效(已激活且未过期)的角色名称
+func (r *userRepository) ListActiveRoleNames(ctx context.Context, userID uint) ([]string, error) {
+	if userID == 0 {
+		return nil, fmt.Errorf("用户ID不能为空")
+	}
+
+	var names []string
+	err := database.Conn(ctx, r.db).Model(&models.UserRole{}).
+		Joins("JOIN roles ON roles.id = user_roles.role_id AND roles.is_active = ? AND roles.deleted_at IS NULL", true).
+		Where("user_roles.user_id = ? AND user_roles.is_active = ?", userID, true).
+		Where("user_roles.expires_at IS NULL OR user_roles.expires_at > ?", time.Now()).
+		Distinct().
+		Pluck("roles.name", &names).Error
+	if err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+// ListActiveUserIDsByRole 获取当前有效(已激活且未过期)地拥有指定角色的正常状态用户ID
+func (r *userRepository) ListActiveUserIDsByRole(ctx context.Context, roleName string) ([]uint, error) {
+	if roleName == "" {
+		return nil, fmt.Errorf("角色名称不能为空")
+	}
+
+	var ids []uint
+	err := database.Conn(ctx, r.db).Model(&models.UserRole{}).
+		Joins("JOIN roles ON roles.id = user_roles.role_id AND roles.is_active = ? AND roles.deleted_at IS NULL", true).
+		Joins("JOIN users ON users.id = user_roles.user_id AND users.status = ? AND users.deleted_at IS NULL", "active").
+		Where("roles.name = ? AND user_roles.is_active = ?", roleName, true).
+		Where("user_roles.expires_at IS NULL OR user_roles.expires_at > ?", time.Now()).
+		Distinct().
+		Pluck("user_roles.user_id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// DeleteExpiredSessions 物理删除一批在指定时间前过期的会话，返回删除的记录数
+//
+// 每次最多删除 limit 条，调用方循环调用直到返回值小于 limit
+func (r *userRepository) DeleteExpiredSessions(ctx context.Context, before time.Time, limit int) (int64, error) {
+	if limit <= 0 {
+		return 0, fmt.Errorf("批量大小必须大于0")
+	}
+
+	var ids []uint
+	err := database.Conn(ctx, r.db).Unscoped().Model(&models.UserSession{}).
+		Where("expires_at < ?", before).
+		Limit(limit).
+		Pluck("id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+
+	result := database.Conn(ctx, r.db).Unscoped().Where("id IN ?", ids).Delete(&models.UserSession{})
+	return result.RowsAffected, result.Error
+}
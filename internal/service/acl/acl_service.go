@@ -0,0 +1,133 @@
+package acl
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	filerepo "cloudpan/internal/repository/file"
+	"cloudpan/internal/repository/models"
+)
+
+// maxInheritanceDepth 向上查找授权的最大文件夹层级，与文件服务的层级上限一致
+const maxInheritanceDepth = 256
+
+// PermissionService 文件访问权限服务接口
+//
+// 文件所有者始终拥有全部权限；管理员可以把文件或文件夹的 read/write/share/delete 权限
+// 授予其他用户或团队，授予团队时对团队的全部活跃成员生效。文件夹上的授权向下继承，
+// 用户对文件的有效权限为文件本身及全部上级文件夹上授予该用户和其所在团队的权限的并集。
+// 任何授权都包含 read 权限；团队删除后恢复期限内，授予该团队的权限对归档成员只保留 read
+//
+// 文件服务按所有者检查权限，文件处理器通过 ResolveActingUser 把获得授权的用户
+// 换成文件所有者再调用文件服务；没有授权时仍以当前用户调用，由文件服务按原有规则拒绝
+//
+// 使用示例：
+//
+//	service := NewPermissionService(grantRepo, fileRepo, userRepo, teamRepo, logger)
+//	actingUserID, err := service.ResolveActingUser(ctx, userID, fileID, models.FilePermissionWrite)
+//	grant, err := service.Grant(ctx, adminID, folderID, &GrantRequest{SubjectType: models.GrantSubjectTeam, SubjectID: teamID, Permissions: []string{"read", "write"}})
+type PermissionService interface {
+	// 权限判断
+	ResolveActingUser(ctx context.Context, userID, fileID uint, permission string) (uint, error)
+	Authorize(ctx context.Context, userID, fileID uint, permission string) error
+	EffectivePermissions(ctx context.Context, userID, fileID uint) (*EffectivePermissions, error)
+
+	// 授权管理
+	ListGrants(ctx context.Context, fileID uint) ([]*GrantInfo, error)
+	Grant(ctx context.Context, adminID, fileID uint, req *GrantRequest) (*GrantInfo, error)
+	Revoke(ctx context.Context, fileID, grantID uint) (*GrantInfo, error)
+}
+
+// FileReader 读取文件记录，由文件仓储实现
+type FileReader interface {
+	GetByID(ctx context.Context, id uint) (*models.File, error)
+}
+
+// UserReader 读取用户记录，由用户仓储实现
+type UserReader interface {
+	GetByID(ctx context.Context, id uint) (*models.User, error)
+}
+
+// TeamReader 读取团队和用户所在团队，由团队仓储实现
+type TeamReader interface {
+	GetByID(ctx context.Context, id uint) (*models.Team, error)
+	ListTeamRolesByMember(ctx context.Context, userID uint) (map[uint]string, error)
+	ListArchivedTeamIDsByMember(ctx context.Context, userID uint) ([]uint, error)
+}
+
+// GrantRequest 授权请求，同一文件对同一用户或团队再次授权时覆盖原有权限
+type GrantRequest struct {
+	SubjectType string   `json:"subject_type" binding:"required"` // 授权对象类型(user/team)
+	SubjectID   uint     `json:"subject_id" binding:"required"`   // 用户ID或团队ID
+	Permissions []string `json:"permissions" binding:"required"`  // 权限(read/write/share/delete)，自动包含read
+}
+
+// GrantInfo 授权信息
+type GrantInfo struct {
+	ID          uint      `json:"id"`           // 授权ID
+	FileID      uint      `json:"file_id"`      // 文件或文件夹ID
+	SubjectType string    `json:"subject_type"` // 授权对象类型
+	SubjectID   uint      `json:"subject_id"`   // 用户ID或团队ID
+	Permissions []string  `json:"permissions"`  // 权限
+	GrantedBy   uint      `json:"granted_by"`   // 授权的管理员ID
+	CreatedAt   time.Time `json:"created_at"`   // 创建时间
+	UpdatedAt   time.Time `json:"updated_at"`   // 更新时间
+}
+
+// EffectivePermissions 用户对文件的有效权限
+type EffectivePermissions struct {
+	FileID      uint     `json:"file_id"`     // 文件或文件夹ID
+	UserID      uint     `json:"user_id"`     // 用户ID
+	IsOwner     bool     `json:"is_owner"`    // 是否文件所有者
+	Permissions []string `json:"permissions"` // 有效权限，没有权限时为空
+	SourceIDs   []uint   `json:"source_ids"`  // 生效的授权ID(含上级文件夹上的授权)
+}
+
+// Has 检查是否包含指定权限
+func (p *EffectivePermissions) Has(permission string) bool {
+	for _, granted := range p.Permissions {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// permissionService 文件访问权限服务实现
+type permissionService struct {
+	grants filerepo.GrantRepository
+	files  FileReader
+	users  UserReader
+	teams  TeamReader
+	logger *zap.Logger
+}
+
+// NewPermissionService 创建文件访问权限服务实例
+func NewPermissionService(grants filerepo.GrantRepository, files FileReader, users UserReader, teams TeamReader, logger *zap.Logger) PermissionService {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &permissionService{
+		grants: grants,
+		files:  files,
+		users:  users,
+		teams:  teams,
+		logger: logger,
+	}
+}
+
+// toGrantInfo 转换授权信息
+func toGrantInfo(grant *models.FileGrant) *GrantInfo {
+	return &GrantInfo{
+		ID:          grant.ID,
+		FileID:      grant.FileID,
+		SubjectType: grant.SubjectType,
+		SubjectID:   grant.SubjectID,
+		Permissions: grant.PermissionList(),
+		GrantedBy:   grant.GrantedBy,
+		CreatedAt:   grant.CreatedAt,
+		UpdatedAt:   grant.UpdatedAt,
+	}
+}
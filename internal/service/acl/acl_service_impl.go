@@ -0,0 +1,331 @@
+package acl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/repository/models"
+)
+
+// ResolveActingUser 返回调用文件服务时使用的用户ID
+//
+// 用户是文件所有者或拥有所需权限时分别返回用户本身和文件所有者；文件不存在或没有权限时
+// 返回用户本身，由文件服务按原有规则返回不存在或无权访问
+func (s *permissionService) ResolveActingUser(ctx context.Context, userID, fileID uint, permission string) (uint, error) {
+	if userID == 0 || fileID == 0 {
+		return userID, nil
+	}
+
+	file, err := s.getFile(ctx, fileID)
+	if err != nil {
+		if errors.Is(err, pkgErrors.ErrResourceNotFound) {
+			return userID, nil
+		}
+		return 0, err
+	}
+	if file.UserID == userID {
+		return userID, nil
+	}
+
+	effective, err := s.evaluate(ctx, userID, file)
+	if err != nil {
+		return 0, err
+	}
+	if effective.Has(permission) {
+		return file.UserID, nil
+	}
+	return userID, nil
+}
+
+// Authorize 检查用户对文件是否拥有指定权限
+func (s *permissionService) Authorize(ctx context.Context, userID, fileID uint, permission string) error {
+	effective, err := s.EffectivePermissions(ctx, userID, fileID)
+	if err != nil {
+		return err
+	}
+	if !effective.Has(permission) {
+		return pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, "无权访问该文件")
+	}
+	return nil
+}
+
+// EffectivePermissions 计算用户对文件的有效权限
+func (s *permissionService) EffectivePermissions(ctx context.Context, userID, fileID uint) (*EffectivePermissions, error) {
+	if userID == 0 || fileID == 0 {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "用户ID和文件ID不能为空")
+	}
+
+	file, err := s.getFile(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+	if file.UserID == userID {
+		return &EffectivePermissions{
+			FileID:      fileID,
+			UserID:      userID,
+			IsOwner:     true,
+			Permissions: append([]string(nil), models.FilePermissions...),
+			SourceIDs:   []uint{},
+		}, nil
+	}
+	return s.evaluate(ctx, userID, file)
+}
+
+// ListGrants 列出文件上的授权
+func (s *permissionService) ListGrants(ctx context.Context, fileID uint) ([]*GrantInfo, error) {
+	if _, err := s.getFile(ctx, fileID); err != nil {
+		return nil, err
+	}
+
+	grants, err := s.grants.ListByFile(ctx, fileID)
+	if err != nil {
+		return nil, fmt.Errorf("查询文件授权失败: %w", err)
+	}
+	infos := make([]*GrantInfo, 0, len(grants))
+	for _, grant := range grants {
+		infos = append(infos, toGrantInfo(grant))
+	}
+	return infos, nil
+}
+
+// Grant 授予用户或团队文件权限，已有授权时覆盖
+func (s *permissionService) Grant(ctx context.Context, adminID, fileID uint, req *GrantRequest) (*GrantInfo, error) {
+	if adminID == 0 || fileID == 0 || req == nil {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "管理员ID、文件ID和授权内容不能为空")
+	}
+	if req.SubjectID == 0 {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "授权对象ID不能为空")
+	}
+	permissions, err := normalizePermissions(req.Permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := s.getFile(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkSubject(ctx, file, req.SubjectType, req.SubjectID); err != nil {
+		return nil, err
+	}
+
+	grant := &models.FileGrant{
+		FileID:      fileID,
+		SubjectType: req.SubjectType,
+		SubjectID:   req.SubjectID,
+		Permissions: strings.Join(permissions, ","),
+		GrantedBy:   adminID,
+	}
+	if err := s.grants.Upsert(ctx, grant); err != nil {
+		return nil, err
+	}
+	// 覆盖已有授权时写入的记录没有ID和创建时间，重新读取
+	saved, err := s.grants.GetBySubject(ctx, fileID, req.SubjectType, req.SubjectID)
+	if err != nil {
+		return nil, fmt.Errorf("读取文件授权失败: %w", err)
+	}
+
+	s.logger.Info("File permissions granted",
+		zap.Uint("admin_id", adminID),
+		zap.Uint("file_id", fileID),
+		zap.String("subject_type", req.SubjectType),
+		zap.Uint("subject_id", req.SubjectID),
+		zap.Strings("permissions", permissions))
+	return toGrantInfo(saved), nil
+}
+
+// Revoke 撤销文件上的授权，返回被撤销的授权
+func (s *permissionService) Revoke(ctx context.Context, fileID, grantID uint) (*GrantInfo, error) {
+	if fileID == 0 || grantID == 0 {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "文件ID和授权ID不能为空")
+	}
+
+	grant, err := s.grants.GetByID(ctx, grantID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "授权不存在")
+		}
+		return nil, fmt.Errorf("获取文件授权失败: %w", err)
+	}
+	if grant.FileID != fileID {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "授权不存在")
+	}
+
+	if err := s.grants.Delete(ctx, grantID); err != nil {
+		return nil, fmt.Errorf("删除文件授权失败: %w", err)
+	}
+
+	s.logger.Info("File permissions revoked",
+		zap.Uint("file_id", fileID),
+		zap.Uint("grant_id", grantID),
+		zap.String("subject_type", grant.SubjectType),
+		zap.Uint("subject_id", grant.SubjectID))
+	return toGrantInfo(grant), nil
+}
+
+// getFile 获取文件，不存在时返回资源不存在错误
+func (s *permissionService) getFile(ctx context.Context, fileID uint) (*models.File, error) {
+	if fileID == 0 {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "文件ID不能为空")
+	}
+
+	file, err := s.files.GetByID(ctx, fileID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "文件不存在")
+		}
+		return nil, fmt.Errorf("获取文件失败: %w", err)
+	}
+	return file, nil
+}
+
+// evaluate 计算非所有者用户对文件的有效权限
+//
+// 从文件开始向上查找同一所有者的上级文件夹，合并这些文件上授予用户和其所在团队的权限；
+// 已删除团队的授权冻结为只读，团队只读成员(viewer)通过团队授权最多获得查看权限
+func (s *permissionService) evaluate(ctx context.Context, userID uint, file *models.File) (*EffectivePermissions, error) {
+	fileIDs, err := s.ancestry(ctx, file)
+	if err != nil {
+		return nil, err
+	}
+
+	var teamIDs []uint
+	readOnly := make(map[uint]bool)
+	if s.teams != nil {
+		roles, err := s.teams.ListTeamRolesByMember(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		for teamID, role := range roles {
+			teamIDs = append(teamIDs, teamID)
+			if role == models.TeamRoleViewer {
+				readOnly[teamID] = true
+			}
+		}
+		archivedIDs, err := s.teams.ListArchivedTeamIDsByMember(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		for _, teamID := range archivedIDs {
+			readOnly[teamID] = true
+		}
+		teamIDs = append(teamIDs, archivedIDs...)
+	}
+
+	grants, err := s.grants.ListForSubjects(ctx, fileIDs, userID, teamIDs)
+	if err != nil {
+		return nil, fmt.Errorf("查询文件授权失败: %w", err)
+	}
+
+	effective := &EffectivePermissions{
+		FileID:      file.ID,
+		UserID:      userID,
+		Permissions: []string{},
+		SourceIDs:   make([]uint, 0, len(grants)),
+	}
+	granted := make(map[string]bool)
+	for _, grant := range grants {
+		effective.SourceIDs = append(effective.SourceIDs, grant.ID)
+		granted[models.FilePermissionRead] = true
+		if grant.SubjectType == models.GrantSubjectTeam && readOnly[grant.SubjectID] {
+			continue
+		}
+		for _, permission := range grant.PermissionList() {
+			granted[permission] = true
+		}
+	}
+	for _, permission := range models.FilePermissions {
+		if granted[permission] {
+			effective.Permissions = append(effective.Permissions, permission)
+		}
+	}
+	return effective, nil
+}
+
+// ancestry 返回文件及其同一所有者的全部上级文件夹ID，从文件本身开始
+func (s *permissionService) ancestry(ctx context.Context, file *models.File) ([]uint, error) {
+	fileIDs := []uint{file.ID}
+	current := file
+	for depth := 0; current.ParentID != nil; depth++ {
+		if depth >= maxInheritanceDepth {
+			return nil, fmt.Errorf("文件夹层级超过上限: %d", maxInheritanceDepth)
+		}
+
+		parent, err := s.files.GetByID(ctx, *current.ParentID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				break
+			}
+			return nil, fmt.Errorf("获取上级文件夹失败: %w", err)
+		}
+		if parent.UserID != file.UserID {
+			break
+		}
+		fileIDs = append(fileIDs, parent.ID)
+		current = parent
+	}
+	return fileIDs, nil
+}
+
+// checkSubject 检查授权对象存在且不是文件所有者
+func (s *permissionService) checkSubject(ctx context.Context, file *models.File, subjectType string, subjectID uint) error {
+	switch subjectType {
+	case models.GrantSubjectUser:
+		if subjectID == file.UserID {
+			return pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "文件所有者已拥有全部权限")
+		}
+		if _, err := s.users.GetByID(ctx, subjectID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "用户不存在")
+			}
+			return fmt.Errorf("获取用户失败: %w", err)
+		}
+	case models.GrantSubjectTeam:
+		if s.teams == nil {
+			return pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "团队功能不可用")
+		}
+		team, err := s.teams.GetByID(ctx, subjectID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "团队不存在")
+			}
+			return fmt.Errorf("获取团队失败: %w", err)
+		}
+		if team.IsArchived() {
+			return pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "团队已删除，不能授权")
+		}
+	default:
+		return pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "无效的授权对象类型: %s", subjectType)
+	}
+	return nil
+}
+
+// normalizePermissions 校验权限并去重，结果按固定顺序排列且始终包含read
+func normalizePermissions(permissions []string) ([]string, error) {
+	if len(permissions) == 0 {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "权限不能为空")
+	}
+
+	requested := map[string]bool{models.FilePermissionRead: true}
+	for _, permission := range permissions {
+		permission = strings.ToLower(strings.TrimSpace(permission))
+		if !models.IsValidFilePermission(permission) {
+			return nil, pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "无效的权限: %s", permission)
+		}
+		requested[permission] = true
+	}
+
+	normalized := make([]string, 0, len(requested))
+	for _, permission := range models.FilePermissions {
+		if requested[permission] {
+			normalized = append(normalized, permission)
+		}
+	}
+	return normalized, nil
+}
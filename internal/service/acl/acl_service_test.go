@@ -0,0 +1,277 @@
+package acl
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/repository/models"
+)
+
+// memoryGrants 内存授权仓储
+type memoryGrants struct {
+	grants []*models.FileGrant
+	nextID uint
+}
+
+func (m *memoryGrants) Upsert(_ context.Context, grant *models.FileGrant) error {
+	for _, existing := range m.grants {
+		if existing.FileID == grant.FileID && existing.SubjectType == grant.SubjectType && existing.SubjectID == grant.SubjectID {
+			existing.Permissions, existing.GrantedBy = grant.Permissions, grant.GrantedBy
+			return nil
+		}
+	}
+	m.nextID++
+	copied := *grant
+	copied.ID = m.nextID
+	m.grants = append(m.grants, &copied)
+	return nil
+}
+
+func (m *memoryGrants) GetByID(_ context.Context, id uint) (*models.FileGrant, error) {
+	for _, grant := range m.grants {
+		if grant.ID == id {
+			copied := *grant
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *memoryGrants) GetBySubject(_ context.Context, fileID uint, subjectType string, subjectID uint) (*models.FileGrant, error) {
+	for _, grant := range m.grants {
+		if grant.FileID == fileID && grant.SubjectType == subjectType && grant.SubjectID == subjectID {
+			copied := *grant
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *memoryGrants) Delete(_ context.Context, id uint) error {
+	for i, grant := range m.grants {
+		if grant.ID == id {
+			m.grants = append(m.grants[:i], m.grants[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (m *memoryGrants) ListByFile(_ context.Context, fileID uint) ([]*models.FileGrant, error) {
+	var grants []*models.FileGrant
+	for _, grant := range m.grants {
+		if grant.FileID == fileID {
+			grants = append(grants, grant)
+		}
+	}
+	return grants, nil
+}
+
+func (m *memoryGrants) ListForSubjects(_ context.Context, fileIDs []uint, userID uint, teamIDs []uint) ([]*models.FileGrant, error) {
+	var grants []*models.FileGrant
+	for _, grant := range m.grants {
+		if !containsID(fileIDs, grant.FileID) {
+			continue
+		}
+		if (grant.SubjectType == models.GrantSubjectUser && grant.SubjectID == userID) ||
+			(grant.SubjectType == models.GrantSubjectTeam && containsID(teamIDs, grant.SubjectID)) {
+			grants = append(grants, grant)
+		}
+	}
+	return grants, nil
+}
+
+func containsID(ids []uint, id uint) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
+
+// memoryFiles 内存文件、用户和团队仓储
+type memoryFiles struct {
+	files    map[uint]*models.File
+	users    map[uint]bool
+	teams    map[uint]bool
+	members  map[uint]map[uint]string // 用户ID -> 团队ID -> 成员角色
+	archived map[uint][]uint
+}
+
+func (m *memoryFiles) GetByID(_ context.Context, id uint) (*models.File, error) {
+	file, ok := m.files[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *file
+	return &copied, nil
+}
+
+type memoryUsers struct{ *memoryFiles }
+
+func (m memoryUsers) GetByID(_ context.Context, id uint) (*models.User, error) {
+	if !m.users[id] {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &models.User{}, nil
+}
+
+type memoryTeams struct{ *memoryFiles }
+
+func (m memoryTeams) GetByID(_ context.Context, id uint) (*models.Team, error) {
+	if !m.teams[id] {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &models.Team{}, nil
+}
+
+func (m memoryTeams) ListTeamRolesByMember(_ context.Context, userID uint) (map[uint]string, error) {
+	return m.members[userID], nil
+}
+
+func (m memoryTeams) ListArchivedTeamIDsByMember(_ context.Context, userID uint) ([]uint, error) {
+	return m.archived[userID], nil
+}
+
+func uintPtr(v uint) *uint { return &v }
+
+// newTestService 创建测试服务：用户1拥有文件夹10 > 文件夹11 > 文件12，用户2属于团队7
+func newTestService() (*permissionService, *memoryGrants) {
+	store := &memoryFiles{
+		files: map[uint]*models.File{
+			10: {UserID: 1, IsFolder: true},
+			11: {UserID: 1, IsFolder: true, ParentID: uintPtr(10)},
+			12: {UserID: 1, ParentID: uintPtr(11)},
+		},
+		users:   map[uint]bool{1: true, 2: true, 3: true},
+		teams:   map[uint]bool{7: true},
+		members: map[uint]map[uint]string{2: {7: models.TeamRoleMember}},
+	}
+	for id, file := range store.files {
+		file.ID = id
+	}
+	grants := &memoryGrants{}
+	service := NewPermissionService(grants, store, memoryUsers{store}, memoryTeams{store}, nil).(*permissionService)
+	return service, grants
+}
+
+func TestPermissionService_Inheritance(t *testing.T) {
+	ctx := context.Background()
+	service, _ := newTestService()
+
+	_, err := service.Grant(ctx, 99, 10, &GrantRequest{SubjectType: models.GrantSubjectTeam, SubjectID: 7, Permissions: []string{"share"}})
+	require.NoError(t, err)
+	_, err = service.Grant(ctx, 99, 11, &GrantRequest{SubjectType: models.GrantSubjectUser, SubjectID: 2, Permissions: []string{"write"}})
+	require.NoError(t, err)
+
+	// 文件继承团队在顶层文件夹上的授权和用户在中间文件夹上的授权，且隐含read
+	effective, err := service.EffectivePermissions(ctx, 2, 12)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"read", "write", "share"}, effective.Permissions)
+	assert.Len(t, effective.SourceIDs, 2)
+
+	// 上级文件夹不继承子文件夹上的授权
+	effective, err = service.EffectivePermissions(ctx, 2, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"read", "share"}, effective.Permissions)
+
+	acting, err := service.ResolveActingUser(ctx, 2, 12, models.FilePermissionWrite)
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), acting)
+	acting, err = service.ResolveActingUser(ctx, 2, 12, models.FilePermissionDelete)
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), acting) // 没有权限时仍以当前用户调用
+	acting, err = service.ResolveActingUser(ctx, 3, 404, models.FilePermissionRead)
+	require.NoError(t, err)
+	assert.Equal(t, uint(3), acting)
+
+	assert.True(t, errors.Is(service.Authorize(ctx, 3, 12, models.FilePermissionRead), pkgErrors.ErrPermissionDenied))
+	assert.NoError(t, service.Authorize(ctx, 1, 12, models.FilePermissionDelete))
+}
+
+func TestPermissionService_GrantAndRevoke(t *testing.T) {
+	ctx := context.Background()
+	service, grants := newTestService()
+
+	_, err := service.Grant(ctx, 99, 12, &GrantRequest{SubjectType: models.GrantSubjectUser, SubjectID: 1, Permissions: []string{"read"}})
+	assert.True(t, errors.Is(err, pkgErrors.ErrInvalidInput)) // 所有者不需要授权
+	_, err = service.Grant(ctx, 99, 12, &GrantRequest{SubjectType: models.GrantSubjectUser, SubjectID: 2, Permissions: []string{"admin"}})
+	assert.True(t, errors.Is(err, pkgErrors.ErrInvalidInput))
+	_, err = service.Grant(ctx, 99, 12, &GrantRequest{SubjectType: models.GrantSubjectTeam, SubjectID: 8, Permissions: []string{"read"}})
+	assert.True(t, errors.Is(err, pkgErrors.ErrResourceNotFound))
+	_, err = service.Grant(ctx, 99, 404, &GrantRequest{SubjectType: models.GrantSubjectUser, SubjectID: 2, Permissions: []string{"read"}})
+	assert.True(t, errors.Is(err, pkgErrors.ErrResourceNotFound))
+
+	grant, err := service.Grant(ctx, 99, 12, &GrantRequest{SubjectType: models.GrantSubjectUser, SubjectID: 2, Permissions: []string{"Delete", "write", "write"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"read", "write", "delete"}, grant.Permissions)
+
+	// 再次授权覆盖原有权限
+	grant, err = service.Grant(ctx, 98, 12, &GrantRequest{SubjectType: models.GrantSubjectUser, SubjectID: 2, Permissions: []string{"read"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"read"}, grant.Permissions)
+	assert.Equal(t, uint(98), grant.GrantedBy)
+	require.Len(t, grants.grants, 1)
+
+	_, err = service.Revoke(ctx, 11, grant.ID)
+	assert.True(t, errors.Is(err, pkgErrors.ErrResourceNotFound)) // 授权不属于该文件
+	revoked, err := service.Revoke(ctx, 12, grant.ID)
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), revoked.SubjectID)
+
+	list, err := service.ListGrants(ctx, 12)
+	require.NoError(t, err)
+	assert.Empty(t, list)
+}
+
+func TestPermissionService_TeamViewer(t *testing.T) {
+	ctx := context.Background()
+	service, _ := newTestService()
+	store := service.teams.(memoryTeams)
+
+	_, err := service.Grant(ctx, 99, 10, &GrantRequest{SubjectType: models.GrantSubjectTeam, SubjectID: 7, Permissions: []string{"write", "delete", "share"}})
+	require.NoError(t, err)
+
+	// 团队只读成员通过团队授权只获得read
+	store.members[2] = map[uint]string{7: models.TeamRoleViewer}
+	effective, err := service.EffectivePermissions(ctx, 2, 12)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"read"}, effective.Permissions)
+	assert.True(t, errors.Is(service.Authorize(ctx, 2, 12, models.FilePermissionWrite), pkgErrors.ErrPermissionDenied))
+	assert.NoError(t, service.Authorize(ctx, 2, 12, models.FilePermissionRead))
+	acting, err := service.ResolveActingUser(ctx, 2, 12, models.FilePermissionWrite)
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), acting, "没有写入权限时不以所有者身份执行")
+
+	// 单独授予用户的权限不受团队角色限制
+	_, err = service.Grant(ctx, 99, 12, &GrantRequest{SubjectType: models.GrantSubjectUser, SubjectID: 2, Permissions: []string{"write"}})
+	require.NoError(t, err)
+	assert.NoError(t, service.Authorize(ctx, 2, 12, models.FilePermissionWrite))
+	assert.True(t, errors.Is(service.Authorize(ctx, 2, 12, models.FilePermissionDelete), pkgErrors.ErrPermissionDenied))
+}
+
+func TestPermissionService_ArchivedTeam(t *testing.T) {
+	ctx := context.Background()
+	service, _ := newTestService()
+	store := service.teams.(memoryTeams)
+
+	_, err := service.Grant(ctx, 99, 10, &GrantRequest{SubjectType: models.GrantSubjectTeam, SubjectID: 7, Permissions: []string{"write", "delete"}})
+	require.NoError(t, err)
+
+	// 团队删除后归档成员的团队授权只保留read
+	store.members[2] = nil
+	store.archived = map[uint][]uint{2: {7}}
+	effective, err := service.EffectivePermissions(ctx, 2, 12)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"read"}, effective.Permissions)
+	acting, err := service.ResolveActingUser(ctx, 2, 12, models.FilePermissionWrite)
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), acting)
+}
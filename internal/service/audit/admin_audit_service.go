@@ -0,0 +1,117 @@
+// Package audit 管理员操作审计和安全审计
+//
+// 管理员的特权操作在执行成功后写入只追加的审计日志，记录操作者、操作对象、变更前后的值和原因，
+// 供合规审查时按管理员、操作类型、操作对象和时间范围查询。
+// 登录、修改密码、创建分享、权限变更和删除等安全相关操作写入安全审计日志，见 SecurityAuditService
+package audit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"cloudpan/internal/repository/models"
+	systemrepo "cloudpan/internal/repository/system"
+)
+
+// 管理员操作类型
+const (
+	ActionUserSuspend        = "user.suspend"         // 停用用户
+	ActionUserActivate       = "user.activate"        // 恢复用户
+	ActionUserQuotaChange    = "user.quota_change"    // 调整存储配额
+	ActionUserImpersonate    = "user.impersonate"     // 模拟用户登录
+	ActionUserPasswordReset  = "user.password_reset"  // 强制重置密码
+	ActionFeatureFlagChange  = "feature_flag.change"  // 修改特性开关
+	ActionCacheWarmup        = "cache.warmup"         // 重新预热参考数据缓存
+	ActionSSOProviderChange  = "sso_provider.change"  // 修改单点登录身份提供方或认领域名
+	ActionSCIMTokenChange    = "sso_provider.scim"    // 签发或吊销目录同步令牌
+	ActionEmailRetry         = "email.retry"          // 重新发送死信邮件
+	ActionEmailPurge         = "email.purge"          // 清除死信邮件
+	ActionEmailTemplateSave  = "email.template_save"  // 在线修改邮件模板
+	ActionEmailTemplateReset = "email.template_reset" // 恢复邮件模板为内置或模板目录中的版本
+	ActionFileGrantChange    = "file.grant"           // 授予或撤销文件访问权限
+	ActionFileContentRestore = "file.content_restore" // 从删除后保留的存储对象恢复文件
+	ActionFileTransferForce  = "file.transfer_force"  // 强制转移文件所有权(离职交接)
+	ActionLogLevelChange     = "system.log_level"     // 运行时修改应用日志级别
+	ActionShareAbuseResolve  = "share.abuse_resolve"  // 审核分享举报(驳回或确认违规)
+	ActionModerationOverride = "file.moderation"      // 复核分享图片的内容审核结果(放行或禁止)
+)
+
+// 操作对象类型
+const (
+	TargetUser          = "user"           // 用户，对象ID为用户ID
+	TargetFeatureFlag   = "feature_flag"   // 特性开关，对象ID为特性键
+	TargetCache         = "cache"          // 参考数据缓存，对象ID为数据源名称
+	TargetSSOProvider   = "sso_provider"   // 单点登录身份提供方，对象ID为身份提供方ID
+	TargetEmail         = "email"          // 死信邮件，对象ID为邮件ID，清空死信队列时为 *
+	TargetEmailTemplate = "email_template" // 邮件模板，对象ID为 模板名称/语言
+	TargetFile          = "file"           // 文件或文件夹，对象ID为文件ID
+	TargetSystem        = "system"         // 系统设置，对象ID为设置项名称
+	TargetShare         = "share"          // 文件分享，对象ID为分享ID
+)
+
+// Entry 一条待写入的审计记录
+type Entry struct {
+	ActorID    uint                   // 操作的管理员ID
+	Action     string                 // 操作类型
+	TargetType string                 // 操作对象类型
+	TargetID   string                 // 操作对象ID
+	Before     map[string]interface{} // 操作前的值，没有时为nil
+	After      map[string]interface{} // 操作后的值，没有时为nil
+	Reason     string                 // 操作原因
+	IPAddress  string                 // 请求IP
+	UserAgent  string                 // 请求User-Agent
+	RequestID  string                 // 请求ID
+}
+
+// Query 审计日志查询条件
+type Query struct {
+	ActorID    uint      // 操作的管理员ID，0表示不限
+	Action     string    // 操作类型
+	TargetType string    // 操作对象类型
+	TargetID   string    // 操作对象ID
+	From       time.Time // 起始时间(包含)，零值表示不限
+	To         time.Time // 结束时间(不包含)，零值表示不限
+	Page       int       // 页码，从1开始
+	PageSize   int       // 每页记录数
+}
+
+// AdminAuditService 管理员操作审计服务接口
+//
+// 审计日志只允许追加，写入后不能修改或删除。
+// 写入失败时由调用方记录错误日志，已执行的操作不回滚
+//
+// 使用示例：
+//
+//	service := audit.NewAdminAuditService(systemrepo.NewAdminAuditRepository(db), logger)
+//	err := service.Record(ctx, &audit.Entry{ActorID: adminID, Action: audit.ActionUserSuspend, TargetType: audit.TargetUser, TargetID: "42", Reason: reason})
+//	logs, total, err := service.List(ctx, audit.Query{Action: audit.ActionUserSuspend, Page: 1, PageSize: 20})
+type AdminAuditService interface {
+	Record(ctx context.Context, entries ...*Entry) error
+	List(ctx context.Context, query Query) ([]*models.AdminAuditLog, int64, error)
+}
+
+// AdminAuditStore 审计日志读写，由管理员审计日志仓储实现
+type AdminAuditStore interface {
+	Create(ctx context.Context, logs []*models.AdminAuditLog) error
+	List(ctx context.Context, filter systemrepo.AdminAuditFilter, limit, offset int) ([]*models.AdminAuditLog, int64, error)
+}
+
+var (
+	defaultMu      sync.RWMutex
+	defaultService AdminAuditService
+)
+
+// SetDefault 设置全局管理员操作审计服务，启动时由main调用
+func SetDefault(service AdminAuditService) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultService = service
+}
+
+// Default 返回全局管理员操作审计服务，未创建时返回nil
+func Default() AdminAuditService {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultService
+}
@@ -0,0 +1,108 @@
+package audit
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	basemodels "cloudpan/internal/pkg/database/models"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+	systemrepo "cloudpan/internal/repository/system"
+)
+
+// 字段长度上限，与审计日志表结构一致
+const (
+	maxReasonLength    = 500
+	maxUserAgentLength = 1000
+	maxTargetIDLength  = 100
+)
+
+// 分页默认值
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// adminAuditService 管理员操作审计服务实现
+type adminAuditService struct {
+	store  AdminAuditStore
+	logger *zap.Logger
+	now    func() time.Time
+}
+
+// NewAdminAuditService 创建管理员操作审计服务实例
+func NewAdminAuditService(store AdminAuditStore, logger *zap.Logger) AdminAuditService {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &adminAuditService{
+		store:  store,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// Record 写入审计记录，同一批记录使用相同的操作时间
+func (s *adminAuditService) Record(ctx context.Context, entries ...*Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	now := s.now()
+	logs := make([]*models.AdminAuditLog, 0, len(entries))
+	for _, entry := range entries {
+		if entry.ActorID == 0 || entry.Action == "" || entry.TargetType == "" {
+			return pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "审计记录缺少操作者、操作类型或操作对象")
+		}
+		logs = append(logs, &models.AdminAuditLog{
+			ActorID:    entry.ActorID,
+			Action:     entry.Action,
+			TargetType: entry.TargetType,
+			TargetID:   utils.Truncate(entry.TargetID, maxTargetIDLength),
+			Reason:     utils.Truncate(entry.Reason, maxReasonLength),
+			Before:     toJSONMap(entry.Before),
+			After:      toJSONMap(entry.After),
+			IPAddress:  entry.IPAddress,
+			UserAgent:  utils.Truncate(entry.UserAgent, maxUserAgentLength),
+			RequestID:  entry.RequestID,
+			CreatedAt:  now,
+		})
+	}
+	return s.store.Create(ctx, logs)
+}
+
+// List 按条件分页查询审计记录，按时间倒序
+func (s *adminAuditService) List(ctx context.Context, query Query) ([]*models.AdminAuditLog, int64, error) {
+	if !query.From.IsZero() && !query.To.IsZero() && !query.From.Before(query.To) {
+		return nil, 0, pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "起始时间必须早于结束时间")
+	}
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.PageSize < 1 {
+		query.PageSize = defaultPageSize
+	}
+	query.PageSize = min(query.PageSize, maxPageSize)
+
+	filter := systemrepo.AdminAuditFilter{
+		ActorID:    query.ActorID,
+		Action:     query.Action,
+		TargetType: query.TargetType,
+		TargetID:   query.TargetID,
+		From:       query.From,
+		To:         query.To,
+	}
+	return s.store.List(ctx, filter, query.PageSize, (query.Page-1)*query.PageSize)
+}
+
+// toJSONMap 转换变更内容，空值不写入
+func toJSONMap(values map[string]interface{}) *basemodels.JSONMap {
+	if len(values) == 0 {
+		return nil
+	}
+	m := basemodels.JSONMap(values)
+	return &m
+}
@@ -0,0 +1,127 @@
+package audit
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/repository/models"
+	systemrepo "cloudpan/internal/repository/system"
+)
+
+// memoryAuditStore 内存审计日志存储
+type memoryAuditStore struct {
+	logs   []*models.AdminAuditLog
+	filter systemrepo.AdminAuditFilter
+	limit  int
+	offset int
+}
+
+func (m *memoryAuditStore) Create(_ context.Context, logs []*models.AdminAuditLog) error {
+	m.logs = append(m.logs, logs...)
+	return nil
+}
+
+func (m *memoryAuditStore) List(_ context.Context, filter systemrepo.AdminAuditFilter, limit, offset int) ([]*models.AdminAuditLog, int64, error) {
+	m.filter, m.limit, m.offset = filter, limit, offset
+	return m.logs, int64(len(m.logs)), nil
+}
+
+var auditTestNow = time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+
+func newTestAuditService() (*adminAuditService, *memoryAuditStore) {
+	store := &memoryAuditStore{}
+	svc := NewAdminAuditService(store, nil).(*adminAuditService)
+	svc.now = func() time.Time { return auditTestNow }
+	return svc, store
+}
+
+func TestAdminAuditService_Record(t *testing.T) {
+	svc, store := newTestAuditService()
+
+	err := svc.Record(context.Background(),
+		&Entry{
+			ActorID:    1,
+			Action:     ActionUserQuotaChange,
+			TargetType: TargetUser,
+			TargetID:   "42",
+			Before:     map[string]interface{}{"storage_quota": 10},
+			After:      map[string]interface{}{"storage_quota": 20},
+			Reason:     strings.Repeat("原", maxReasonLength+10),
+			IPAddress:  "10.0.0.1",
+			RequestID:  "req-1",
+		},
+		&Entry{ActorID: 1, Action: ActionCacheWarmup, TargetType: TargetCache, TargetID: "plans"},
+	)
+	require.NoError(t, err)
+	require.Len(t, store.logs, 2)
+
+	first := store.logs[0]
+	assert.Equal(t, uint(1), first.ActorID)
+	assert.Equal(t, "42", first.TargetID)
+	assert.Equal(t, 10, (*first.Before)["storage_quota"])
+	assert.Equal(t, 20, (*first.After)["storage_quota"])
+	assert.Equal(t, maxReasonLength, len([]rune(first.Reason)))
+	assert.Equal(t, "req-1", first.RequestID)
+	assert.Equal(t, auditTestNow, first.CreatedAt)
+
+	// 没有变更内容时不写入空JSON
+	assert.Nil(t, store.logs[1].Before)
+	assert.Nil(t, store.logs[1].After)
+	assert.Equal(t, auditTestNow, store.logs[1].CreatedAt)
+}
+
+func TestAdminAuditService_RecordRejectsIncompleteEntries(t *testing.T) {
+	svc, store := newTestAuditService()
+
+	err := svc.Record(context.Background(),
+		&Entry{ActorID: 1, Action: ActionUserSuspend, TargetType: TargetUser, TargetID: "1"},
+		&Entry{Action: ActionUserSuspend, TargetType: TargetUser, TargetID: "2"},
+	)
+	assert.ErrorIs(t, err, pkgErrors.ErrInvalidInput)
+	// 同一批记录要么全部写入要么全部不写入
+	assert.Empty(t, store.logs)
+
+	assert.NoError(t, svc.Record(context.Background()))
+}
+
+func TestAdminAuditService_List(t *testing.T) {
+	svc, store := newTestAuditService()
+	from := auditTestNow.Add(-24 * time.Hour)
+
+	_, _, err := svc.List(context.Background(), Query{
+		ActorID:    7,
+		Action:     ActionUserSuspend,
+		TargetType: TargetUser,
+		TargetID:   "42",
+		From:       from,
+		To:         auditTestNow,
+		Page:       3,
+		PageSize:   500,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, systemrepo.AdminAuditFilter{
+		ActorID:    7,
+		Action:     ActionUserSuspend,
+		TargetType: TargetUser,
+		TargetID:   "42",
+		From:       from,
+		To:         auditTestNow,
+	}, store.filter)
+	assert.Equal(t, maxPageSize, store.limit)
+	assert.Equal(t, 2*maxPageSize, store.offset)
+
+	// 默认第一页
+	_, _, err = svc.List(context.Background(), Query{})
+	require.NoError(t, err)
+	assert.Equal(t, defaultPageSize, store.limit)
+	assert.Equal(t, 0, store.offset)
+
+	_, _, err = svc.List(context.Background(), Query{From: auditTestNow, To: from})
+	assert.ErrorIs(t, err, pkgErrors.ErrInvalidInput)
+}
@@ -0,0 +1,161 @@
+package audit
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"cloudpan/internal/repository/models"
+	systemrepo "cloudpan/internal/repository/system"
+)
+
+// 安全审计操作类型，操作分类为第一个点号之前的部分
+const (
+	SecurityActionLogin            = "auth.login"            // 登录成功
+	SecurityActionLoginFailed      = "auth.login_failed"     // 登录失败
+	SecurityActionPasswordChange   = "auth.password_change"  // 修改密码
+	SecurityActionPasswordReset    = "auth.password_reset"   // 重置密码(找回密码或管理员强制重置)
+	SecurityActionAPIKeyCreate     = "auth.api_key_create"   // 创建API密钥
+	SecurityActionAPIKeyRevoke     = "auth.api_key_revoke"   // 撤销API密钥
+	SecurityActionShareCreate      = "share.create"          // 创建分享
+	SecurityActionPermissionGrant  = "permission.grant"      // 授予文件访问权限
+	SecurityActionPermissionRevoke = "permission.revoke"     // 撤销文件访问权限
+	SecurityActionTeamRoleChange   = "permission.team_role"  // 修改团队成员角色
+	SecurityActionTransferStart    = "permission.transfer"   // 发起文件所有权转移
+	SecurityActionOwnerChange      = "permission.owner"      // 文件所有权转移完成(接收方接受或管理员强制转移)
+	SecurityActionFileTrash        = "delete.file_trash"     // 文件移入回收站
+	SecurityActionFilePurge        = "delete.file_permanent" // 彻底删除回收站项目
+	SecurityActionTrashEmpty       = "delete.trash_empty"    // 清空回收站
+	SecurityActionTeamDelete       = "delete.team"           // 删除团队
+	SecurityActionTeamRestore      = "delete.team_restore"   // 恢复已删除的团队
+	SecurityActionTeamMemberRemove = "delete.team_member"    // 移除团队成员
+	SecurityActionFileMetadataBulk = "file.metadata_bulk"    // 批量修改文件标签和属性，多个文件合并为一条记录
+
+	SecurityActionInactivityWarning   = "account.inactivity_warning"   // 发送长期未登录提醒
+	SecurityActionInactivityDowngrade = "account.inactivity_downgrade" // 长期未登录降低存储配额
+	SecurityActionInactivityFreeze    = "account.inactivity_freeze"    // 长期未登录冻结账户
+	SecurityActionInactivityDeletion  = "account.inactivity_deletion"  // 长期未登录计划删除账户
+	SecurityActionInactivityRestore   = "account.inactivity_restore"   // 重新登录后恢复长期未登录的处置
+	SecurityActionAccountMerge        = "account.merge"                // 合并账户，来源账户停用，文件、团队和身份关联转到目标账户
+)
+
+// 安全审计操作分类
+const (
+	SecurityCategoryAuth       = "auth"       // 认证
+	SecurityCategoryShare      = "share"      // 分享
+	SecurityCategoryPermission = "permission" // 权限变更
+	SecurityCategoryDelete     = "delete"     // 删除
+	SecurityCategoryAccount    = "account"    // 账户生命周期(长期未登录处置、账户合并)
+	SecurityCategoryFile       = "file"       // 文件元数据变更
+)
+
+// 安全审计操作对象类型
+const (
+	ResourceUser      = "user"       // 用户，对象ID为用户ID
+	ResourceFile      = "file"       // 文件或文件夹，对象ID为文件ID
+	ResourceShare     = "share"      // 分享，对象ID为分享ID
+	ResourceTeam      = "team"       // 团队，对象ID为团队ID
+	ResourceTrashItem = "trash_item" // 回收站项目，对象ID为项目ID
+	ResourceAPIKey    = "api_key"    // API密钥，对象ID为密钥ID
+)
+
+// securitySeverities 操作类型的严重程度，未列出的为low
+var securitySeverities = map[string]string{
+	SecurityActionLoginFailed:      models.AuditSeverityMedium,
+	SecurityActionPasswordChange:   models.AuditSeverityMedium,
+	SecurityActionPasswordReset:    models.AuditSeverityHigh,
+	SecurityActionAPIKeyCreate:     models.AuditSeverityMedium,
+	SecurityActionAPIKeyRevoke:     models.AuditSeverityMedium,
+	SecurityActionPermissionGrant:  models.AuditSeverityMedium,
+	SecurityActionPermissionRevoke: models.AuditSeverityMedium,
+	SecurityActionTeamRoleChange:   models.AuditSeverityMedium,
+	SecurityActionTransferStart:    models.AuditSeverityMedium,
+	SecurityActionOwnerChange:      models.AuditSeverityHigh,
+	SecurityActionFilePurge:        models.AuditSeverityHigh,
+	SecurityActionTrashEmpty:       models.AuditSeverityHigh,
+	SecurityActionTeamDelete:       models.AuditSeverityHigh,
+	SecurityActionTeamRestore:      models.AuditSeverityMedium,
+	SecurityActionTeamMemberRemove: models.AuditSeverityMedium,
+
+	SecurityActionInactivityDowngrade: models.AuditSeverityMedium,
+	SecurityActionInactivityFreeze:    models.AuditSeverityHigh,
+	SecurityActionInactivityDeletion:  models.AuditSeverityHigh,
+	SecurityActionAccountMerge:        models.AuditSeverityHigh,
+}
+
+// SecurityCategory 返回操作类型的分类
+func SecurityCategory(action string) string {
+	category, _, _ := strings.Cut(action, ".")
+	return category
+}
+
+// Event 一条待写入的安全审计记录
+type Event struct {
+	UserID        uint                   // 操作用户ID，未识别用户(如登录时用户不存在)为0
+	Action        string                 // 操作类型
+	ResourceType  string                 // 操作对象类型
+	ResourceID    string                 // 操作对象ID
+	ResourceName  string                 // 操作对象名称
+	Result        string                 // 操作结果，默认success
+	Before        map[string]interface{} // 操作前的快照，没有时为nil
+	After         map[string]interface{} // 操作后的快照，没有时为nil
+	Details       map[string]interface{} // 附加信息，如失败原因
+	IPAddress     string                 // 请求IP
+	UserAgent     string                 // 请求User-Agent
+	RequestID     string                 // 请求ID
+	RequestMethod string                 // 请求方法
+	RequestURI    string                 // 请求URI
+}
+
+// SecurityQuery 安全审计日志查询条件
+type SecurityQuery struct {
+	UserID   uint      // 操作用户ID，0表示不限
+	Action   string    // 操作类型
+	Category string    // 操作分类(auth/share/permission/delete)
+	Result   string    // 操作结果(success/failure)
+	From     time.Time // 起始时间(包含)，零值表示不限
+	To       time.Time // 结束时间(不包含)，零值表示不限
+	Page     int       // 页码，从1开始
+	PageSize int       // 每页记录数
+}
+
+// SecurityAuditService 安全审计服务接口
+//
+// 审计日志只允许追加，写入后不能修改或删除；严重程度和分类由操作类型决定。
+// 写入失败时由调用方记录错误日志，已执行的操作不回滚
+//
+// 使用示例：
+//
+//	service := audit.NewSecurityAuditService(systemrepo.NewAuditLogRepository(db), logger)
+//	err := service.Record(ctx, &audit.Event{UserID: userID, Action: audit.SecurityActionPasswordChange, ResourceType: audit.ResourceUser, ResourceID: "42"})
+//	logs, total, err := service.List(ctx, audit.SecurityQuery{UserID: 42, Category: audit.SecurityCategoryAuth, Page: 1, PageSize: 20})
+type SecurityAuditService interface {
+	Record(ctx context.Context, events ...*Event) error
+	List(ctx context.Context, query SecurityQuery) ([]*models.AuditLog, int64, error)
+}
+
+// SecurityAuditStore 安全审计日志读写，由安全审计日志仓储实现
+type SecurityAuditStore interface {
+	Create(ctx context.Context, logs []*models.AuditLog) error
+	List(ctx context.Context, filter systemrepo.AuditLogFilter, limit, offset int) ([]*models.AuditLog, int64, error)
+}
+
+var (
+	defaultSecurityMu      sync.RWMutex
+	defaultSecurityService SecurityAuditService
+)
+
+// SetDefaultSecurity 设置全局安全审计服务，启动时由main调用
+func SetDefaultSecurity(service SecurityAuditService) {
+	defaultSecurityMu.Lock()
+	defer defaultSecurityMu.Unlock()
+	defaultSecurityService = service
+}
+
+// DefaultSecurity 返回全局安全审计服务，未创建时返回nil
+func DefaultSecurity() SecurityAuditService {
+	defaultSecurityMu.RLock()
+	defer defaultSecurityMu.RUnlock()
+	return defaultSecurityService
+}
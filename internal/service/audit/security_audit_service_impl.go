@@ -0,0 +1,122 @@
+package audit
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+	systemrepo "cloudpan/internal/repository/system"
+)
+
+// 安全审计字段长度上限，与审计日志表结构一致
+const (
+	maxResourceNameLength = 255
+	maxRequestURILength   = 1000
+	maxRequestIDLength    = 100
+)
+
+// securityAuditService 安全审计服务实现
+type securityAuditService struct {
+	store  SecurityAuditStore
+	logger *zap.Logger
+	now    func() time.Time
+}
+
+// NewSecurityAuditService 创建安全审计服务实例
+func NewSecurityAuditService(store SecurityAuditStore, logger *zap.Logger) SecurityAuditService {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &securityAuditService{
+		store:  store,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// Record 写入安全审计记录，同一批记录使用相同的操作时间
+func (s *securityAuditService) Record(ctx context.Context, events ...*Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	now := s.now()
+	logs := make([]*models.AuditLog, 0, len(events))
+	for _, event := range events {
+		if event.Action == "" || event.ResourceType == "" {
+			return pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "审计记录缺少操作类型或操作对象")
+		}
+		result := event.Result
+		if result == "" {
+			result = models.AuditResultSuccess
+		}
+		severity, ok := securitySeverities[event.Action]
+		if !ok {
+			severity = models.AuditSeverityLow
+		}
+
+		log := &models.AuditLog{
+			Action:        event.Action,
+			Category:      SecurityCategory(event.Action),
+			ResourceType:  event.ResourceType,
+			ResourceID:    optionalString(utils.Truncate(event.ResourceID, maxTargetIDLength)),
+			ResourceName:  optionalString(utils.Truncate(event.ResourceName, maxResourceNameLength)),
+			Result:        result,
+			Severity:      severity,
+			Before:        toJSONMap(event.Before),
+			After:         toJSONMap(event.After),
+			Details:       toJSONMap(event.Details),
+			IPAddress:     event.IPAddress,
+			UserAgent:     optionalString(utils.Truncate(event.UserAgent, maxUserAgentLength)),
+			RequestID:     optionalString(utils.Truncate(event.RequestID, maxRequestIDLength)),
+			RequestMethod: optionalString(event.RequestMethod),
+			RequestURI:    optionalString(utils.Truncate(event.RequestURI, maxRequestURILength)),
+			CreatedAt:     now,
+		}
+		if event.UserID != 0 {
+			userID := event.UserID
+			log.UserID = &userID
+		}
+		logs = append(logs, log)
+	}
+	return s.store.Create(ctx, logs)
+}
+
+// List 按条件分页查询安全审计记录，按时间倒序
+func (s *securityAuditService) List(ctx context.Context, query SecurityQuery) ([]*models.AuditLog, int64, error) {
+	if !query.From.IsZero() && !query.To.IsZero() && !query.From.Before(query.To) {
+		return nil, 0, pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "起始时间必须早于结束时间")
+	}
+	if query.Result != "" && query.Result != models.AuditResultSuccess && query.Result != models.AuditResultFailure {
+		return nil, 0, pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "无效的操作结果: %s", query.Result)
+	}
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.PageSize < 1 {
+		query.PageSize = defaultPageSize
+	}
+	query.PageSize = min(query.PageSize, maxPageSize)
+
+	filter := systemrepo.AuditLogFilter{
+		UserID:   query.UserID,
+		Action:   query.Action,
+		Category: query.Category,
+		Result:   query.Result,
+		From:     query.From,
+		To:       query.To,
+	}
+	return s.store.List(ctx, filter, query.PageSize, (query.Page-1)*query.PageSize)
+}
+
+// optionalString 空字符串返回nil
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
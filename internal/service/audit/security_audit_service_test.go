@@ -0,0 +1,106 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/repository/models"
+	systemrepo "cloudpan/internal/repository/system"
+)
+
+// memorySecurityStore 内存安全审计日志存储
+type memorySecurityStore struct {
+	logs   []*models.AuditLog
+	filter systemrepo.AuditLogFilter
+	limit  int
+	offset int
+}
+
+func (m *memorySecurityStore) Create(_ context.Context, logs []*models.AuditLog) error {
+	m.logs = append(m.logs, logs...)
+	return nil
+}
+
+func (m *memorySecurityStore) List(_ context.Context, filter systemrepo.AuditLogFilter, limit, offset int) ([]*models.AuditLog, int64, error) {
+	m.filter, m.limit, m.offset = filter, limit, offset
+	return m.logs, int64(len(m.logs)), nil
+}
+
+func newTestSecurityService() (*securityAuditService, *memorySecurityStore) {
+	store := &memorySecurityStore{}
+	svc := NewSecurityAuditService(store, nil).(*securityAuditService)
+	svc.now = func() time.Time { return auditTestNow }
+	return svc, store
+}
+
+func TestSecurityAuditService_Record(t *testing.T) {
+	svc, store := newTestSecurityService()
+
+	err := svc.Record(context.Background(),
+		&Event{
+			Action:       SecurityActionLoginFailed,
+			ResourceType: ResourceUser,
+			Result:       models.AuditResultFailure,
+			Details:      map[string]interface{}{"identifier": "alice", "reason": "user_not_found"},
+			IPAddress:    "10.0.0.1",
+			UserAgent:    strings.Repeat("a", maxUserAgentLength+10),
+		},
+		&Event{
+			UserID:        42,
+			Action:        SecurityActionFilePurge,
+			ResourceType:  ResourceTrashItem,
+			ResourceID:    "7",
+			ResourceName:  "report.pdf",
+			Before:        map[string]interface{}{"size": 1024},
+			RequestMethod: "DELETE",
+			RequestURI:    "/api/v1/trash/7",
+		},
+	)
+	require.NoError(t, err)
+	require.Len(t, store.logs, 2)
+
+	failed := store.logs[0]
+	assert.Nil(t, failed.UserID) // 未识别用户
+	assert.Equal(t, SecurityCategoryAuth, failed.Category)
+	assert.Equal(t, models.AuditSeverityMedium, failed.Severity)
+	assert.False(t, failed.IsSuccessful())
+	assert.Len(t, *failed.UserAgent, maxUserAgentLength)
+	assert.Nil(t, failed.ResourceID)
+	assert.Nil(t, failed.Before)
+
+	purge := store.logs[1]
+	require.NotNil(t, purge.UserID)
+	assert.Equal(t, uint(42), *purge.UserID)
+	assert.Equal(t, SecurityCategoryDelete, purge.Category)
+	assert.Equal(t, models.AuditResultSuccess, purge.Result)
+	assert.True(t, purge.IsHighRisk())
+	assert.Equal(t, 1024, (*purge.Before)["size"])
+	assert.Equal(t, auditTestNow, purge.CreatedAt)
+
+	err = svc.Record(context.Background(), &Event{UserID: 1, ResourceType: ResourceUser})
+	assert.True(t, errors.Is(err, pkgErrors.ErrInvalidInput))
+}
+
+func TestSecurityAuditService_List(t *testing.T) {
+	svc, store := newTestSecurityService()
+	ctx := context.Background()
+
+	_, _, err := svc.List(ctx, SecurityQuery{UserID: 42, Category: SecurityCategoryAuth, Page: 3, PageSize: 500})
+	require.NoError(t, err)
+	assert.Equal(t, uint(42), store.filter.UserID)
+	assert.Equal(t, SecurityCategoryAuth, store.filter.Category)
+	assert.Equal(t, maxPageSize, store.limit)
+	assert.Equal(t, 2*maxPageSize, store.offset)
+
+	_, _, err = svc.List(ctx, SecurityQuery{From: auditTestNow, To: auditTestNow})
+	assert.True(t, errors.Is(err, pkgErrors.ErrInvalidInput))
+	_, _, err = svc.List(ctx, SecurityQuery{Result: "maybe"})
+	assert.True(t, errors.Is(err, pkgErrors.ErrInvalidInput))
+}
@@ -0,0 +1,558 @@
+package automation
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"cloudpan/internal/repository/models"
+)
+
+// 条件表达式限制
+const (
+	MaxConditionLength      = 1000 // 表达式最大长度(字节)，与数据库字段长度一致
+	maxConditionComparisons = 50   // 最多的比较条件数
+	maxConditionDepth       = 20   // 括号和not的最大嵌套层数
+)
+
+// ConditionError 条件表达式语法或类型错误
+type ConditionError struct {
+	Pos int    // 出错位置(从1开始的字符序号)
+	Msg string // 错误说明
+}
+
+// Error 实现error接口
+func (e *ConditionError) Error() string {
+	return fmt.Sprintf("条件表达式第%d个字符处%s", e.Pos, e.Msg)
+}
+
+// FileFacts 条件表达式可以引用的文件属性
+type FileFacts struct {
+	Name     string   // 文件名
+	Ext      string   // 扩展名(小写，不含点)
+	MimeType string   // MIME类型
+	Path     string   // 完整路径
+	Size     int64    // 文件大小(字节)
+	Tags     []string // 标签
+}
+
+// FactsFromFile 提取文件的条件属性
+func FactsFromFile(file *models.File) FileFacts {
+	facts := FileFacts{
+		Name: file.Name,
+		Path: file.GetFullPath(),
+		Size: file.Size,
+	}
+	if i := strings.LastIndexByte(file.Name, '.'); i > 0 && i < len(file.Name)-1 {
+		facts.Ext = strings.ToLower(file.Name[i+1:])
+	}
+	if file.MimeType != nil {
+		facts.MimeType = *file.MimeType
+	}
+	if file.Tags != nil {
+		facts.Tags = splitTags(*file.Tags)
+	}
+	return facts
+}
+
+// Condition 已解析的条件表达式
+//
+// 语法：
+//
+//	ext == "pdf" and size > 10MB
+//	name matches "invoice_*" or tags contains "财务"
+//	mime startswith "image/" and not (ext in ("gif", "bmp"))
+//
+// 字段：name、ext、mime、path(字符串)，size(字节数，可带KB/MB/GB/TB单位，按1024换算)，tags(标签列表)。
+// 字符串字段支持 == != contains startswith endswith matches(通配符*和?) in，比较时不区分大小写；
+// size支持 == != > >= < <=；tags只支持contains。条件用 and、or、not 和括号组合，and优先于or
+type Condition struct {
+	source string
+	root   conditionNode
+}
+
+// ParseCondition 解析条件表达式，空表达式总是匹配
+func ParseCondition(expr string) (*Condition, error) {
+	expr = strings.TrimSpace(expr)
+	if len(expr) > MaxConditionLength {
+		return nil, &ConditionError{Pos: 1, Msg: fmt.Sprintf("：表达式不能超过%d个字符", MaxConditionLength)}
+	}
+	if expr == "" {
+		return &Condition{}, nil
+	}
+
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &conditionParser{tokens: tokens}
+	root, err := p.parseOr(0)
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, p.errorf(tok, "：多余的内容 %q", tok.text)
+	}
+	return &Condition{source: expr, root: root}, nil
+}
+
+// String 返回原始表达式
+func (c *Condition) String() string {
+	return c.source
+}
+
+// Match 判断文件属性是否满足条件
+func (c *Condition) Match(facts FileFacts) bool {
+	if c == nil || c.root == nil {
+		return true
+	}
+	return c.root.eval(facts)
+}
+
+// conditionNode 表达式节点
+type conditionNode interface {
+	eval(facts FileFacts) bool
+}
+
+// andNode 逻辑与
+type andNode struct{ left, right conditionNode }
+
+func (n andNode) eval(f FileFacts) bool { return n.left.eval(f) && n.right.eval(f) }
+
+// orNode 逻辑或
+type orNode struct{ left, right conditionNode }
+
+func (n orNode) eval(f FileFacts) bool { return n.left.eval(f) || n.right.eval(f) }
+
+// notNode 逻辑非
+type notNode struct{ inner conditionNode }
+
+func (n notNode) eval(f FileFacts) bool { return !n.inner.eval(f) }
+
+// stringCompare 字符串字段比较，值已转为小写
+type stringCompare struct {
+	field  string
+	op     string
+	values []string
+}
+
+func (n stringCompare) eval(f FileFacts) bool {
+	var actual string
+	switch n.field {
+	case "name":
+		actual = f.Name
+	case "ext":
+		actual = f.Ext
+	case "mime":
+		actual = f.MimeType
+	case "path":
+		actual = f.Path
+	}
+	actual = strings.ToLower(actual)
+	value := n.values[0]
+
+	switch n.op {
+	case "==":
+		return actual == value
+	case "!=":
+		return actual != value
+	case "contains":
+		return strings.Contains(actual, value)
+	case "startswith":
+		return strings.HasPrefix(actual, value)
+	case "endswith":
+		return strings.HasSuffix(actual, value)
+	case "matches":
+		matched, _ := path.Match(value, actual)
+		return matched
+	case "in":
+		for _, v := range n.values {
+			if actual == v {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sizeCompare 文件大小比较
+type sizeCompare struct {
+	op    string
+	value int64
+}
+
+func (n sizeCompare) eval(f FileFacts) bool {
+	switch n.op {
+	case "==":
+		return f.Size == n.value
+	case "!=":
+		return f.Size != n.value
+	case ">":
+		return f.Size > n.value
+	case ">=":
+		return f.Size >= n.value
+	case "<":
+		return f.Size < n.value
+	case "<=":
+		return f.Size <= n.value
+	}
+	return false
+}
+
+// tagsContain 标签包含，不区分大小写
+type tagsContain struct {
+	value string
+}
+
+func (n tagsContain) eval(f FileFacts) bool {
+	for _, tag := range f.Tags {
+		if strings.ToLower(tag) == n.value {
+			return true
+		}
+	}
+	return false
+}
+
+// 字段类型
+const (
+	fieldString = iota
+	fieldSize
+	fieldTags
+)
+
+// conditionFields 可引用的字段及其类型
+var conditionFields = map[string]int{
+	"name": fieldString,
+	"ext":  fieldString,
+	"mime": fieldString,
+	"path": fieldString,
+	"size": fieldSize,
+	"tags": fieldTags,
+}
+
+// fieldOperators 各类型字段支持的运算符
+var fieldOperators = map[int]map[string]bool{
+	fieldString: {"==": true, "!=": true, "contains": true, "startswith": true, "endswith": true, "matches": true, "in": true},
+	fieldSize:   {"==": true, "!=": true, ">": true, ">=": true, "<": true, "<=": true},
+	fieldTags:   {"contains": true},
+}
+
+// sizeUnits 大小单位
+var sizeUnits = map[string]float64{
+	"":   1,
+	"b":  1,
+	"kb": 1 << 10,
+	"mb": 1 << 20,
+	"gb": 1 << 30,
+	"tb": 1 << 40,
+}
+
+// tokenKind 词法单元类型
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOperator
+	tokenLParen
+	tokenRParen
+	tokenComma
+)
+
+// token 词法单元
+type token struct {
+	kind tokenKind
+	text string // 原文，字符串为去掉引号和转义后的值
+	pos  int    // 从1开始的字符序号
+}
+
+// tokenize 将表达式切分为词法单元
+func tokenize(expr string) ([]token, error) {
+	var tokens []token
+	runes := []rune(expr)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		pos := i + 1
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "(", pos: pos})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")", pos: pos})
+			i++
+		case r == ',':
+			tokens = append(tokens, token{kind: tokenComma, text: ",", pos: pos})
+			i++
+		case r == '"' || r == '\'':
+			var sb strings.Builder
+			j := i + 1
+			for ; j < len(runes) && runes[j] != r; j++ {
+				if runes[j] == '\\' && j+1 < len(runes) {
+					j++
+				}
+				sb.WriteRune(runes[j])
+			}
+			if j >= len(runes) {
+				return nil, &ConditionError{Pos: pos, Msg: "：字符串缺少结束引号"}
+			}
+			tokens = append(tokens, token{kind: tokenString, text: sb.String(), pos: pos})
+			i = j + 1
+		case r == '=' || r == '!' || r == '>' || r == '<':
+			op := string(r)
+			if i+1 < len(runes) && runes[i+1] == '=' {
+				op += "="
+			}
+			if op == "=" || op == "!" {
+				return nil, &ConditionError{Pos: pos, Msg: fmt.Sprintf("：无效的运算符 %q，比较请使用 == 或 !=", op)}
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: op, pos: pos})
+			i += utf8.RuneCountInString(op)
+		case unicode.IsDigit(r):
+			j := i
+			for j < len(runes) && (unicode.IsDigit(runes[j]) || runes[j] == '.') {
+				j++
+			}
+			for j < len(runes) && unicode.IsLetter(runes[j]) {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: string(runes[i:j]), pos: pos})
+			i = j
+		case unicode.IsLetter(r) || r == '_':
+			j := i
+			for j < len(runes) && (unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j]) || runes[j] == '_') {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: string(runes[i:j]), pos: pos})
+			i = j
+		default:
+			return nil, &ConditionError{Pos: pos, Msg: fmt.Sprintf("：无法识别的字符 %q", r)}
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(runes) + 1}), nil
+}
+
+// conditionParser 递归下降解析器
+type conditionParser struct {
+	tokens      []token
+	pos         int
+	comparisons int
+}
+
+func (p *conditionParser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *conditionParser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// keyword 检查下一个词法单元是否为指定关键字(不区分大小写)
+func (p *conditionParser) keyword(word string) bool {
+	tok := p.peek()
+	return tok.kind == tokenIdent && strings.EqualFold(tok.text, word)
+}
+
+func (p *conditionParser) errorf(tok token, format string, args ...interface{}) error {
+	if tok.kind == tokenEOF {
+		return &ConditionError{Pos: tok.pos, Msg: "：表达式不完整"}
+	}
+	return &ConditionError{Pos: tok.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// parseOr or := and ("or" and)*
+func (p *conditionParser) parseOr(depth int) (conditionNode, error) {
+	left, err := p.parseAnd(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		p.next()
+		right, err := p.parseAnd(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+// parseAnd and := not ("and" not)*
+func (p *conditionParser) parseAnd(depth int) (conditionNode, error) {
+	left, err := p.parseNot(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		p.next()
+		right, err := p.parseNot(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+// parseNot not := "not" not | "(" or ")" | comparison
+func (p *conditionParser) parseNot(depth int) (conditionNode, error) {
+	if depth >= maxConditionDepth {
+		return nil, p.errorf(p.peek(), "：嵌套层数不能超过%d层", maxConditionDepth)
+	}
+	if p.keyword("not") {
+		p.next()
+		inner, err := p.parseNot(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return notNode{inner: inner}, nil
+	}
+	if p.peek().kind == tokenLParen {
+		p.next()
+		inner, err := p.parseOr(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if tok := p.next(); tok.kind != tokenRParen {
+			return nil, p.errorf(tok, "：缺少右括号")
+		}
+		return inner, nil
+	}
+	return p.parseComparison()
+}
+
+// parseComparison comparison := field operator value
+func (p *conditionParser) parseComparison() (conditionNode, error) {
+	fieldTok := p.next()
+	if fieldTok.kind != tokenIdent {
+		return nil, p.errorf(fieldTok, "：应为字段名，实际为 %q", fieldTok.text)
+	}
+	field := strings.ToLower(fieldTok.text)
+	fieldType, ok := conditionFields[field]
+	if !ok {
+		return nil, p.errorf(fieldTok, "：未知字段 %q，可用字段为 name、ext、mime、path、size、tags", fieldTok.text)
+	}
+
+	opTok := p.next()
+	op := strings.ToLower(opTok.text)
+	if opTok.kind != tokenOperator && opTok.kind != tokenIdent {
+		return nil, p.errorf(opTok, "：应为运算符，实际为 %q", opTok.text)
+	}
+	if !fieldOperators[fieldType][op] {
+		return nil, p.errorf(opTok, "：字段 %s 不支持运算符 %q", field, opTok.text)
+	}
+
+	p.comparisons++
+	if p.comparisons > maxConditionComparisons {
+		return nil, p.errorf(fieldTok, "：比较条件不能超过%d个", maxConditionComparisons)
+	}
+
+	switch fieldType {
+	case fieldSize:
+		valueTok := p.next()
+		size, err := parseSize(valueTok)
+		if err != nil {
+			return nil, err
+		}
+		return sizeCompare{op: op, value: size}, nil
+
+	case fieldTags:
+		valueTok := p.next()
+		if valueTok.kind != tokenString {
+			return nil, p.errorf(valueTok, "：tags 只能与字符串比较")
+		}
+		return tagsContain{value: strings.ToLower(valueTok.text)}, nil
+
+	default:
+		var values []string
+		if op == "in" {
+			list, err := p.parseStringList()
+			if err != nil {
+				return nil, err
+			}
+			values = list
+		} else {
+			valueTok := p.next()
+			if valueTok.kind != tokenString {
+				return nil, p.errorf(valueTok, "：字段 %s 只能与字符串比较", field)
+			}
+			values = []string{strings.ToLower(valueTok.text)}
+		}
+		if op == "matches" {
+			if _, err := path.Match(values[0], ""); err != nil {
+				return nil, p.errorf(fieldTok, "：通配符格式错误")
+			}
+		}
+		return stringCompare{field: field, op: op, values: values}, nil
+	}
+}
+
+// parseStringList 解析 ("a", "b") 形式的字符串列表
+func (p *conditionParser) parseStringList() ([]string, error) {
+	if tok := p.next(); tok.kind != tokenLParen {
+		return nil, p.errorf(tok, "：in 后应为括号括起的字符串列表")
+	}
+	var values []string
+	for {
+		tok := p.next()
+		if tok.kind != tokenString {
+			return nil, p.errorf(tok, "：列表中只能包含字符串")
+		}
+		values = append(values, strings.ToLower(tok.text))
+
+		sep := p.next()
+		if sep.kind == tokenRParen {
+			return values, nil
+		}
+		if sep.kind != tokenComma {
+			return nil, p.errorf(sep, "：列表项之间应使用逗号分隔")
+		}
+	}
+}
+
+// parseSize 解析带可选单位的文件大小，如 512、10MB、1.5gb
+func parseSize(tok token) (int64, error) {
+	if tok.kind != tokenNumber {
+		return 0, &ConditionError{Pos: tok.pos, Msg: "：size 只能与数字比较"}
+	}
+	text := strings.ToLower(tok.text)
+	split := strings.IndexFunc(text, unicode.IsLetter)
+	if split < 0 {
+		split = len(text)
+	}
+	unit, ok := sizeUnits[text[split:]]
+	if !ok {
+		return 0, &ConditionError{Pos: tok.pos, Msg: fmt.Sprintf("：未知的大小单位 %q，可用单位为 B、KB、MB、GB、TB", tok.text[split:])}
+	}
+	number, err := strconv.ParseFloat(text[:split], 64)
+	if err != nil || number < 0 {
+		return 0, &ConditionError{Pos: tok.pos, Msg: fmt.Sprintf("：无效的数字 %q", tok.text)}
+	}
+	size := number * unit
+	if size > math.MaxInt64/2 {
+		return 0, &ConditionError{Pos: tok.pos, Msg: "：数值过大"}
+	}
+	return int64(size), nil
+}
+
+// splitTags 拆分逗号分隔的标签，去掉空值
+func splitTags(value string) []string {
+	var tags []string
+	for _, tag := range strings.Split(value, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
@@ -0,0 +1,133 @@
+package automation
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"cloudpan/internal/repository/models"
+)
+
+func TestParseConditionMatch(t *testing.T) {
+	facts := FileFacts{
+		Name:     "Invoice_2024.PDF",
+		Ext:      "pdf",
+		MimeType: "application/pdf",
+		Path:     "/财务/收件箱/Invoice_2024.PDF",
+		Size:     12 << 20,
+		Tags:     []string{"报销", "Q1"},
+	}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{``, true},
+		{`ext == "pdf"`, true},
+		{`ext == "PDF"`, true},
+		{`ext != "pdf"`, false},
+		{`name startswith "invoice_"`, true},
+		{`name endswith ".docx"`, false},
+		{`name contains "2024"`, true},
+		{`name matches "invoice_*.pdf"`, true},
+		{`name matches "invoice_?.pdf"`, false},
+		{`path startswith "/财务/"`, true},
+		{`mime in ("image/png", "application/pdf")`, true},
+		{`ext in ("jpg")`, false},
+		{`size > 10MB`, true},
+		{`size >= 12mb and size <= 12MB`, true},
+		{`size < 1.5KB`, false},
+		{`size == 12582912`, true},
+		{`tags contains "q1"`, true},
+		{`tags contains "发票"`, false},
+		{`ext == "jpg" or size > 1GB`, false},
+		{`ext == "jpg" or ext == "pdf" and size > 1MB`, true},
+		{`(ext == "jpg" or ext == "pdf") and size > 1GB`, false},
+		{`not ext == "pdf"`, false},
+		{`NOT (ext == "jpg") AND name contains 'invoice'`, true},
+		{`not not ext == "pdf"`, true},
+		{`name contains "\"quoted\""`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			condition, err := ParseCondition(tt.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, condition.Match(facts))
+		})
+	}
+}
+
+func TestParseConditionErrors(t *testing.T) {
+	tests := []struct {
+		expr    string
+		message string
+	}{
+		{`owner == "alice"`, "未知字段"},
+		{`ext = "pdf"`, "=="},
+		{`ext == pdf`, "只能与字符串比较"},
+		{`size > "10MB"`, "只能与数字比较"},
+		{`size contains "1"`, "不支持运算符"},
+		{`tags == "a"`, "不支持运算符"},
+		{`size > 10XB`, "未知的大小单位"},
+		{`size > 1.2.3`, "无效的数字"},
+		{`ext == "pdf" and`, "表达式不完整"},
+		{`(ext == "pdf"`, "表达式不完整"},
+		{`ext == "pdf")`, "多余的内容"},
+		{`ext in "pdf"`, "括号"},
+		{`ext in ("pdf" "png")`, "逗号"},
+		{`name == "unterminated`, "结束引号"},
+		{`name matches "[a"`, "通配符"},
+		{`ext == "a" # comment`, "无法识别的字符"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := ParseCondition(tt.expr)
+			require.Error(t, err)
+			var condErr *ConditionError
+			assert.True(t, errors.As(err, &condErr))
+			assert.Contains(t, err.Error(), tt.message)
+		})
+	}
+}
+
+func TestParseConditionLimits(t *testing.T) {
+	t.Run("too long", func(t *testing.T) {
+		_, err := ParseCondition(`name == "` + strings.Repeat("a", MaxConditionLength) + `"`)
+		assert.ErrorContains(t, err, "不能超过")
+	})
+
+	t.Run("too many comparisons", func(t *testing.T) {
+		parts := make([]string, maxConditionComparisons+1)
+		for i := range parts {
+			parts[i] = `size > 1`
+		}
+		_, err := ParseCondition(strings.Join(parts, " or "))
+		assert.ErrorContains(t, err, "比较条件不能超过")
+	})
+
+	t.Run("too deep", func(t *testing.T) {
+		expr := strings.Repeat("(", maxConditionDepth+1) + `ext == "a"` + strings.Repeat(")", maxConditionDepth+1)
+		_, err := ParseCondition(expr)
+		assert.ErrorContains(t, err, "嵌套层数")
+	})
+}
+
+func TestFactsFromFile(t *testing.T) {
+	mime := "image/jpeg"
+	tags := " 旅行, ,家庭 "
+	file := &models.File{Name: "IMG_0001.JPG", Path: "/相册", Size: 2048, MimeType: &mime, Tags: &tags}
+
+	facts := FactsFromFile(file)
+	assert.Equal(t, "jpg", facts.Ext)
+	assert.Equal(t, "/相册/IMG_0001.JPG", facts.Path)
+	assert.Equal(t, "image/jpeg", facts.MimeType)
+	assert.Equal(t, []string{"旅行", "家庭"}, facts.Tags)
+
+	assert.Empty(t, FactsFromFile(&models.File{Name: ".bashrc"}).Ext)
+	assert.Empty(t, FactsFromFile(&models.File{Name: "README"}).Ext)
+}
@@ -0,0 +1,428 @@
+package automation
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/config"
+	filerepo "cloudpan/internal/repository/file"
+	"cloudpan/internal/repository/models"
+	"cloudpan/internal/service/jobs"
+)
+
+// Webhook请求头
+const (
+	HeaderEvent     = "X-Cloudpan-Event"     // 触发事件
+	HeaderRuleID    = "X-Cloudpan-Rule"      // 规则ID
+	HeaderSignature = "X-Cloudpan-Signature" // 请求体签名，格式为 sha256=<HMAC-SHA256十六进制>
+)
+
+const (
+	maxAncestorDepth   = 64   // 向上查找规则所在文件夹的最大层数
+	maxLogMessage      = 1000 // 执行日志说明的最大长度(字节)
+	maxFileTagsLength  = 1000 // 文件标签字段的最大长度(字节)
+	maxWebhookRespRead = 4096 // 读取Webhook响应体的最大字节数
+)
+
+// ErrPrivateAddress Webhook地址解析到内网或本机地址
+var ErrPrivateAddress = errors.New("webhook address is not allowed")
+
+// UploadDispatcher 上传完成事件分发，由上传处理器在文件上传完成后调用
+type UploadDispatcher interface {
+	FileUploaded(file *models.File)
+}
+
+// JobEnqueuer 后台任务提交，由 jobs.Queue 实现
+type JobEnqueuer interface {
+	Enqueue(job jobs.Job) error
+}
+
+// FileStore 规则执行需要的文件读写，由文件仓储实现
+type FileStore interface {
+	GetByID(ctx context.Context, id uint) (*models.File, error)
+	UpdateTags(ctx context.Context, id uint, tags *string) error
+}
+
+// FileMover 移动文件，由 file.TreeService 实现
+type FileMover interface {
+	Move(ctx context.Context, userID, fileID uint, targetParentID *uint) (*models.File, error)
+}
+
+// EngineOptions 规则执行选项
+type EngineOptions struct {
+	WebhookTimeout       time.Duration // 单次Webhook请求超时
+	AllowPrivateWebhooks bool          // 是否允许Webhook访问内网和本机地址
+}
+
+// EngineOptionsFromConfig 从配置构建规则执行选项
+func EngineOptionsFromConfig(cfg config.AutomationConfig) EngineOptions {
+	return EngineOptions{
+		WebhookTimeout:       cfg.WebhookTimeout,
+		AllowPrivateWebhooks: cfg.AllowPrivateWebhooks,
+	}
+}
+
+// WebhookPayload Webhook请求体
+type WebhookPayload struct {
+	Event       string      `json:"event"`        // 触发事件
+	RuleID      uint        `json:"rule_id"`      // 规则ID
+	RuleName    string      `json:"rule_name"`    // 规则名称
+	TriggeredAt time.Time   `json:"triggered_at"` // 触发时间
+	File        WebhookFile `json:"file"`         // 触发规则的文件
+}
+
+// WebhookFile Webhook请求体中的文件信息
+type WebhookFile struct {
+	ID       uint   `json:"id"`
+	UUID     string `json:"uuid"`
+	Name     string `json:"name"`
+	Path     string `json:"path"`
+	Size     int64  `json:"size"`
+	MimeType string `json:"mime_type,omitempty"`
+	Hash     string `json:"hash,omitempty"`
+	ParentID *uint  `json:"parent_id,omitempty"`
+}
+
+// Engine 文件夹规则执行引擎
+//
+// 文件上传完成后提交一个后台任务，任务中沿文件的父链找到所在文件夹及上级文件夹上启用的规则
+// (上级文件夹的规则需开启 IncludeSubfolders)，按创建顺序逐条判断条件并执行动作，
+// 每条执行过的规则记录一条执行日志。动作失败不影响后续规则；移动动作不会再次触发规则
+//
+// 使用示例：
+//
+//	engine := NewEngine(ruleRepo, fileRepo, treeService, jobs.Default(), EngineOptionsFromConfig(cfg.Automation), logger)
+//	engine.FileUploaded(file)
+type Engine struct {
+	rules   filerepo.FolderRuleRepository
+	files   FileStore
+	mover   FileMover
+	queue   JobEnqueuer
+	client  *http.Client
+	options EngineOptions
+	logger  *zap.Logger
+	now     func() time.Time
+}
+
+// NewEngine 创建文件夹规则执行引擎
+//
+// mover 可以为nil，此时移动动作执行失败；queue 可以为nil(如任务队列未启动)，此时上传完成不触发规则
+func NewEngine(rules filerepo.FolderRuleRepository, files FileStore, mover FileMover, queue JobEnqueuer, options EngineOptions, logger *zap.Logger) *Engine {
+	if options.WebhookTimeout <= 0 {
+		options.WebhookTimeout = DefaultWebhookTimeout
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &Engine{
+		rules:   rules,
+		files:   files,
+		mover:   mover,
+		queue:   queue,
+		client:  newWebhookClient(options),
+		options: options,
+		logger:  logger,
+		now:     time.Now,
+	}
+}
+
+// FileUploaded 提交上传完成后的规则执行任务，排队失败只记录日志
+func (e *Engine) FileUploaded(file *models.File) {
+	if file == nil || file.IsFolder || file.ParentID == nil {
+		return
+	}
+	if e.queue == nil {
+		e.logger.Debug("Job queue unavailable, folder rules skipped", zap.Uint("file_id", file.ID))
+		return
+	}
+
+	fileID := file.ID
+	err := e.queue.Enqueue(jobs.Job{
+		Type:     jobs.TypeAutomation,
+		Priority: jobs.PriorityInteractive,
+		Key:      "file:" + strconv.FormatUint(uint64(fileID), 10),
+		Run: func(ctx context.Context) error {
+			return e.Run(ctx, fileID)
+		},
+	})
+	if err != nil {
+		e.logger.Warn("Failed to schedule folder rules",
+			zap.Uint("file_id", fileID),
+			zap.Error(err))
+	}
+}
+
+// Run 对文件执行其所在文件夹上匹配的规则
+//
+// 文件已删除或不可用时直接返回；只有读取文件和规则失败时返回错误
+func (e *Engine) Run(ctx context.Context, fileID uint) error {
+	file, err := e.files.GetByID(ctx, fileID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return fmt.Errorf("获取文件失败: %w", err)
+	}
+	if file.IsFolder || !file.IsActive() || file.ParentID == nil {
+		return nil
+	}
+
+	folderIDs, err := e.ancestors(ctx, file)
+	if err != nil {
+		return err
+	}
+	rules, err := e.rules.ListActiveByFolders(ctx, file.UserID, folderIDs)
+	if err != nil {
+		return fmt.Errorf("获取文件夹规则失败: %w", err)
+	}
+
+	directParent := *file.ParentID
+	for _, rule := range rules {
+		if rule.Event != models.RuleEventFileUploaded {
+			continue
+		}
+		if rule.FolderID != directParent && !rule.IncludeSubfolders {
+			continue
+		}
+		condition, err := ParseCondition(rule.Condition)
+		if err != nil {
+			e.record(ctx, rule, file, e.now(), "", fmt.Errorf("条件表达式无效: %w", err))
+			continue
+		}
+		if !condition.Match(FactsFromFile(file)) {
+			continue
+		}
+
+		started := e.now()
+		message, err := e.execute(ctx, rule, file)
+		e.record(ctx, rule, file, started, message, err)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// ancestors 返回文件的上级文件夹ID，从直接所在文件夹开始
+func (e *Engine) ancestors(ctx context.Context, file *models.File) ([]uint, error) {
+	var ids []uint
+	current := file.ParentID
+	for depth := 0; current != nil && depth < maxAncestorDepth; depth++ {
+		ids = append(ids, *current)
+		folder, err := e.files.GetByID(ctx, *current)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				break
+			}
+			return nil, fmt.Errorf("获取上级文件夹失败: %w", err)
+		}
+		current = folder.ParentID
+	}
+	return ids, nil
+}
+
+// execute 执行规则的动作，成功时返回结果说明
+func (e *Engine) execute(ctx context.Context, rule *models.FolderRule, file *models.File) (string, error) {
+	switch rule.Action {
+	case models.RuleActionWebhook:
+		return e.sendWebhook(ctx, rule, file)
+	case models.RuleActionTag:
+		return e.addTags(ctx, rule, file)
+	case models.RuleActionMove:
+		return e.moveFile(ctx, rule, file)
+	default:
+		return "", fmt.Errorf("不支持的动作类型 %q", rule.Action)
+	}
+}
+
+// sendWebhook 向规则的Webhook地址发送上传事件，2xx响应视为成功
+func (e *Engine) sendWebhook(ctx context.Context, rule *models.FolderRule, file *models.File) (string, error) {
+	payload := WebhookPayload{
+		Event:       rule.Event,
+		RuleID:      rule.ID,
+		RuleName:    rule.Name,
+		TriggeredAt: e.now().UTC(),
+		File: WebhookFile{
+			ID:       file.ID,
+			UUID:     file.UUID,
+			Name:     file.Name,
+			Path:     file.GetFullPath(),
+			Size:     file.Size,
+			ParentID: file.ParentID,
+		},
+	}
+	if file.MimeType != nil {
+		payload.File.MimeType = *file.MimeType
+	}
+	if file.Hash != nil {
+		payload.File.Hash = *file.Hash
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("生成请求体失败: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, rule.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("创建请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "cloudpan-automation")
+	req.Header.Set(HeaderEvent, rule.Event)
+	req.Header.Set(HeaderRuleID, strconv.FormatUint(uint64(rule.ID), 10))
+	if rule.WebhookSecret != "" {
+		req.Header.Set(HeaderSignature, SignPayload(rule.WebhookSecret, body))
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxWebhookRespRead))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("Webhook返回状态码 %d", resp.StatusCode)
+	}
+	return fmt.Sprintf("Webhook返回状态码 %d", resp.StatusCode), nil
+}
+
+// addTags 将规则的标签合并到文件标签中
+func (e *Engine) addTags(ctx context.Context, rule *models.FolderRule, file *models.File) (string, error) {
+	var existing []string
+	if file.Tags != nil {
+		existing = splitTags(*file.Tags)
+	}
+	merged := joinTags(append(existing, splitTags(rule.Tags)...))
+	if file.Tags != nil && merged == joinTags(existing) {
+		return "标签已存在", nil
+	}
+	if len(merged) > maxFileTagsLength {
+		return "", fmt.Errorf("文件标签超过%d个字符", maxFileTagsLength)
+	}
+
+	if err := e.files.UpdateTags(ctx, file.ID, &merged); err != nil {
+		return "", fmt.Errorf("更新标签失败: %w", err)
+	}
+	file.Tags = &merged
+	return "已添加标签 " + rule.Tags, nil
+}
+
+// moveFile 将文件移动到规则的目标文件夹，移动不会再次触发规则
+func (e *Engine) moveFile(ctx context.Context, rule *models.FolderRule, file *models.File) (string, error) {
+	if e.mover == nil {
+		return "", errors.New("移动功能不可用")
+	}
+	moved, err := e.mover.Move(ctx, file.UserID, file.ID, rule.TargetFolderID)
+	if err != nil {
+		return "", err
+	}
+	*file = *moved
+	return "已移动到 " + moved.GetFullPath(), nil
+}
+
+// record 写入执行日志并更新规则的执行统计，写入失败只记录日志
+func (e *Engine) record(ctx context.Context, rule *models.FolderRule, file *models.File, started time.Time, message string, execErr error) {
+	finished := e.now()
+	status := models.RuleLogStatusSuccess
+	if execErr != nil {
+		status = models.RuleLogStatusFailed
+		message = execErr.Error()
+		e.logger.Warn("Folder rule failed",
+			zap.Uint("rule_id", rule.ID),
+			zap.Uint("file_id", file.ID),
+			zap.String("action", rule.Action),
+			zap.Error(execErr))
+	}
+
+	log := &models.FolderRuleLog{
+		RuleID:     rule.ID,
+		FileID:     file.ID,
+		FileName:   file.Name,
+		Action:     rule.Action,
+		Status:     status,
+		Message:    truncateMessage(message),
+		DurationMs: finished.Sub(started).Milliseconds(),
+		CreatedAt:  finished,
+	}
+	if err := e.rules.CreateLog(ctx, log); err != nil {
+		e.logger.Warn("Failed to write folder rule log", zap.Uint("rule_id", rule.ID), zap.Error(err))
+	}
+	if err := e.rules.RecordExecution(ctx, rule.ID, status, finished); err != nil {
+		e.logger.Warn("Failed to record folder rule execution", zap.Uint("rule_id", rule.ID), zap.Error(err))
+	}
+}
+
+// SignPayload 计算Webhook请求体签名，接收方用同一密钥计算后比较 X-Cloudpan-Signature 头
+func SignPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// newWebhookClient 创建Webhook客户端
+//
+// 不使用环境代理、不跟随重定向；除非允许内网地址，否则在建立连接时按实际解析出的IP
+// 拒绝本机、内网、链路本地和组播地址，避免通过DNS解析绕过
+func newWebhookClient(options EngineOptions) *http.Client {
+	dialer := &net.Dialer{Timeout: options.WebhookTimeout}
+	if !options.AllowPrivateWebhooks {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || isPrivateIP(ip) {
+				return fmt.Errorf("%w: %s", ErrPrivateAddress, host)
+			}
+			return nil
+		}
+	}
+	return &http.Client{
+		Timeout: options.WebhookTimeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: options.WebhookTimeout,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// isPrivateIP 判断是否为本机、内网、链路本地、组播或未指定地址
+func isPrivateIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast()
+}
+
+// truncateMessage 截断日志说明，保证不截断多字节字符
+func truncateMessage(message string) string {
+	if len(message) <= maxLogMessage {
+		return message
+	}
+	cut := maxLogMessage
+	for cut > 0 && !utf8.RuneStart(message[cut]) {
+		cut--
+	}
+	return message[:cut]
+}
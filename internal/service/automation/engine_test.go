@@ -0,0 +1,239 @@
+package automation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"cloudpan/internal/repository/models"
+	"cloudpan/internal/service/jobs"
+)
+
+// recordingQueue 记录提交的任务
+type recordingQueue struct {
+	jobs []jobs.Job
+	err  error
+}
+
+func (q *recordingQueue) Enqueue(job jobs.Job) error {
+	if q.err != nil {
+		return q.err
+	}
+	q.jobs = append(q.jobs, job)
+	return nil
+}
+
+// memoryMover 在内存文件仓储中移动文件
+type memoryMover struct {
+	files *memoryFiles
+	calls int
+}
+
+func (m *memoryMover) Move(_ context.Context, userID, fileID uint, targetParentID *uint) (*models.File, error) {
+	m.calls++
+	f := m.files.files[fileID]
+	if f.UserID != userID {
+		return nil, errors.New("forbidden")
+	}
+	f.ParentID = targetParentID
+	f.Path = "/"
+	if targetParentID != nil {
+		f.Path = m.files.files[*targetParentID].GetFullPath()
+	}
+	copied := *f
+	return &copied, nil
+}
+
+// newEngineFixture 用户1的 /收件箱(10)/子目录(11)/report.pdf(30)，以及 /归档(12)
+func newEngineFixture() (*Engine, *memoryRules, *memoryFiles, *memoryMover) {
+	mime := "application/pdf"
+	report := testFile(30, 1, uintPtr(11), "/收件箱/子目录", "report.pdf", false)
+	report.MimeType = &mime
+	report.Size = 2 << 20
+	files := newMemoryFiles(
+		testFile(10, 1, nil, "/", "收件箱", true),
+		testFile(11, 1, uintPtr(10), "/收件箱", "子目录", true),
+		testFile(12, 1, nil, "/", "归档", true),
+		report,
+	)
+	rules := newMemoryRules()
+	mover := &memoryMover{files: files}
+	engine := NewEngine(rules, files, mover, nil, EngineOptions{AllowPrivateWebhooks: true}, nil)
+	return engine, rules, files, mover
+}
+
+func addRule(t *testing.T, rules *memoryRules, rule *models.FolderRule) *models.FolderRule {
+	rule.UserID = 1
+	rule.Event = models.RuleEventFileUploaded
+	rule.IsActive = true
+	require.NoError(t, rules.Create(context.Background(), rule))
+	return rule
+}
+
+func TestEngineFileUploaded(t *testing.T) {
+	engine, _, _, _ := newEngineFixture()
+	queue := &recordingQueue{}
+	engine.queue = queue
+
+	engine.FileUploaded(testFile(30, 1, uintPtr(11), "/收件箱/子目录", "report.pdf", false))
+	engine.FileUploaded(testFile(31, 1, nil, "/", "root.txt", false))
+	engine.FileUploaded(testFile(11, 1, uintPtr(10), "/收件箱", "子目录", true))
+
+	require.Len(t, queue.jobs, 1)
+	assert.Equal(t, jobs.TypeAutomation, queue.jobs[0].Type)
+	assert.Equal(t, jobs.PriorityInteractive, queue.jobs[0].Priority)
+
+	// 队列已满时只记录日志
+	queue.err = jobs.ErrQueueFull
+	engine.FileUploaded(testFile(30, 1, uintPtr(11), "/收件箱/子目录", "report.pdf", false))
+
+	// 未启动队列时跳过
+	engine.queue = nil
+	engine.FileUploaded(testFile(30, 1, uintPtr(11), "/收件箱/子目录", "report.pdf", false))
+}
+
+func TestEngineRunSelectsRules(t *testing.T) {
+	ctx := context.Background()
+	engine, rules, files, _ := newEngineFixture()
+
+	direct := addRule(t, rules, &models.FolderRule{FolderID: 11, Name: "直接", Action: models.RuleActionTag, Tags: "a"})
+	inherited := addRule(t, rules, &models.FolderRule{FolderID: 10, Name: "继承", Action: models.RuleActionTag, Tags: "b", IncludeSubfolders: true})
+	notInherited := addRule(t, rules, &models.FolderRule{FolderID: 10, Name: "仅本级", Action: models.RuleActionTag, Tags: "c"})
+	mismatch := addRule(t, rules, &models.FolderRule{FolderID: 11, Name: "图片", Condition: `mime startswith "image/"`, Action: models.RuleActionTag, Tags: "d"})
+	inactive := addRule(t, rules, &models.FolderRule{FolderID: 11, Name: "停用", Action: models.RuleActionTag, Tags: "e"})
+	rules.rules[inactive.ID].IsActive = false
+
+	require.NoError(t, engine.Run(ctx, 30))
+	require.NotNil(t, files.files[30].Tags)
+	assert.Equal(t, "a,b", *files.files[30].Tags)
+
+	require.Len(t, rules.logs, 2)
+	assert.Equal(t, direct.ID, rules.logs[0].RuleID)
+	assert.Equal(t, inherited.ID, rules.logs[1].RuleID)
+	assert.Equal(t, models.RuleLogStatusSuccess, rules.logs[0].Status)
+	assert.Equal(t, "report.pdf", rules.logs[0].FileName)
+	assert.Equal(t, int64(1), rules.rules[direct.ID].TriggerCount)
+	assert.Zero(t, rules.rules[notInherited.ID].TriggerCount)
+	assert.Zero(t, rules.rules[mismatch.ID].TriggerCount)
+
+	// 再次执行时标签已存在
+	require.NoError(t, engine.Run(ctx, 30))
+	assert.Equal(t, "a,b", *files.files[30].Tags)
+	assert.Equal(t, "标签已存在", rules.logs[2].Message)
+
+	// 文件不存在时直接返回
+	assert.NoError(t, engine.Run(ctx, 999))
+}
+
+func TestEngineRunWebhook(t *testing.T) {
+	ctx := context.Background()
+
+	var received WebhookPayload
+	var headers http.Header
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header.Clone()
+		body, _ = io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &received)
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	t.Run("signed delivery", func(t *testing.T) {
+		engine, rules, _, _ := newEngineFixture()
+		rule := addRule(t, rules, &models.FolderRule{
+			FolderID: 11, Name: "通知", Condition: `ext == "pdf" and size > 1MB`,
+			Action: models.RuleActionWebhook, WebhookURL: server.URL + "/hook", WebhookSecret: "s3cret",
+		})
+
+		require.NoError(t, engine.Run(ctx, 30))
+		assert.Equal(t, models.RuleEventFileUploaded, headers.Get(HeaderEvent))
+		assert.Equal(t, SignPayload("s3cret", body), headers.Get(HeaderSignature))
+		assert.Equal(t, rule.ID, received.RuleID)
+		assert.Equal(t, "/收件箱/子目录/report.pdf", received.File.Path)
+		assert.Equal(t, "application/pdf", received.File.MimeType)
+
+		require.Len(t, rules.logs, 1)
+		assert.Equal(t, models.RuleLogStatusSuccess, rules.logs[0].Status)
+		assert.Contains(t, rules.logs[0].Message, "204")
+	})
+
+	t.Run("non 2xx fails", func(t *testing.T) {
+		engine, rules, _, _ := newEngineFixture()
+		rule := addRule(t, rules, &models.FolderRule{FolderID: 11, Name: "通知", Action: models.RuleActionWebhook, WebhookURL: server.URL + "/fail"})
+
+		require.NoError(t, engine.Run(ctx, 30))
+		require.Len(t, rules.logs, 1)
+		assert.Equal(t, models.RuleLogStatusFailed, rules.logs[0].Status)
+		assert.Contains(t, rules.logs[0].Message, "500")
+		assert.Equal(t, int64(1), rules.rules[rule.ID].FailureCount)
+		assert.Empty(t, headers.Get(HeaderSignature))
+	})
+
+	t.Run("private address blocked", func(t *testing.T) {
+		engine, rules, files, mover := newEngineFixture()
+		engine = NewEngine(rules, files, mover, nil, EngineOptions{}, nil)
+		addRule(t, rules, &models.FolderRule{FolderID: 11, Name: "通知", Action: models.RuleActionWebhook, WebhookURL: server.URL + "/hook"})
+
+		require.NoError(t, engine.Run(ctx, 30))
+		require.Len(t, rules.logs, 1)
+		assert.Equal(t, models.RuleLogStatusFailed, rules.logs[0].Status)
+		assert.Contains(t, rules.logs[0].Message, ErrPrivateAddress.Error())
+	})
+}
+
+func TestEngineRunMove(t *testing.T) {
+	ctx := context.Background()
+	engine, rules, files, mover := newEngineFixture()
+	addRule(t, rules, &models.FolderRule{FolderID: 11, Name: "归档", Action: models.RuleActionMove, TargetFolderID: uintPtr(12)})
+	addRule(t, rules, &models.FolderRule{FolderID: 11, Name: "标记", Condition: `path startswith "/归档/"`, Action: models.RuleActionTag, Tags: "已归档"})
+
+	require.NoError(t, engine.Run(ctx, 30))
+	assert.Equal(t, 1, mover.calls)
+	assert.Equal(t, uintPtr(12), files.files[30].ParentID)
+	require.Len(t, rules.logs, 2)
+	assert.Equal(t, "已移动到 /归档/report.pdf", rules.logs[0].Message)
+	// 后续规则按移动后的路径判断条件
+	assert.Equal(t, "已归档", *files.files[30].Tags)
+
+	t.Run("mover unavailable", func(t *testing.T) {
+		engine, rules, _, _ := newEngineFixture()
+		engine.mover = nil
+		addRule(t, rules, &models.FolderRule{FolderID: 11, Name: "归档", Action: models.RuleActionMove})
+
+		require.NoError(t, engine.Run(ctx, 30))
+		require.Len(t, rules.logs, 1)
+		assert.Equal(t, models.RuleLogStatusFailed, rules.logs[0].Status)
+	})
+}
+
+func TestIsPrivateIP(t *testing.T) {
+	for _, tt := range []struct {
+		ip   string
+		want bool
+	}{
+		{"127.0.0.1", true},
+		{"10.1.2.3", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"::1", true},
+		{"fd00::1", true},
+		{"0.0.0.0", true},
+		{"93.184.216.34", false},
+		{"2606:2800:220:1::", false},
+	} {
+		assert.Equal(t, tt.want, isPrivateIP(net.ParseIP(tt.ip)), tt.ip)
+	}
+}
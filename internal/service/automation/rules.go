@@ -0,0 +1,403 @@
+package automation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/config"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	filerepo "cloudpan/internal/repository/file"
+	"cloudpan/internal/repository/models"
+)
+
+// 规则默认限制
+const (
+	DefaultMaxRulesPerUser = 50
+	DefaultWebhookTimeout  = 10 * time.Second
+	DefaultLogRetention    = 30 * 24 * time.Hour
+
+	maxRuleNameLength      = 100
+	maxWebhookURLLength    = 500
+	maxWebhookSecretLength = 255
+	maxRuleTags            = 10
+	maxRuleTagLength       = 50
+	maxRuleLogPageSize     = 100
+)
+
+// RuleService 文件夹自动化规则管理服务接口
+//
+// 用户在自己的文件夹上设置规则：文件上传到该文件夹(可选包括子文件夹)且满足条件表达式时，
+// 由 Engine 异步执行动作(调用Webhook、添加标签或移动到其他文件夹)，每次执行记录一条日志。
+// 规则保存前校验文件夹归属、条件表达式语法和动作参数，日志超过保留期后由维护任务清理
+//
+// 使用示例：
+//
+//	service := NewRuleService(ruleRepo, fileRepo, RuleOptions{MaxRulesPerUser: 50}, logger)
+//	rule, err := service.CreateRule(ctx, userID, &RuleRequest{FolderID: folderID, Name: "发票归档",
+//		Condition: `ext == "pdf"`, Action: models.RuleActionMove, TargetFolderID: &archiveID})
+//	logs, total, err := service.ListLogs(ctx, userID, rule.ID, 1, 20)
+type RuleService interface {
+	CreateRule(ctx context.Context, userID uint, req *RuleRequest) (*models.FolderRule, error)
+	ListRules(ctx context.Context, userID uint, folderID *uint) ([]*models.FolderRule, error)
+	UpdateRule(ctx context.Context, userID, ruleID uint, req *RuleUpdate) (*models.FolderRule, error)
+	DeleteRule(ctx context.Context, userID, ruleID uint) error
+
+	ListLogs(ctx context.Context, userID, ruleID uint, page, pageSize int) ([]*models.FolderRuleLog, int64, error)
+	PurgeLogs(ctx context.Context, limit int) (int64, error)
+}
+
+// FileReader 读取文件记录，由文件仓储实现
+type FileReader interface {
+	GetByID(ctx context.Context, id uint) (*models.File, error)
+}
+
+// RuleOptions 规则管理选项
+type RuleOptions struct {
+	MaxRulesPerUser int           // 每个用户最多的规则数
+	LogRetention    time.Duration // 执行日志保留时长
+}
+
+// RuleOptionsFromConfig 从配置构建规则管理选项
+func RuleOptionsFromConfig(cfg config.AutomationConfig) RuleOptions {
+	return RuleOptions{
+		MaxRulesPerUser: cfg.MaxRulesPerUser,
+		LogRetention:    cfg.LogRetention,
+	}
+}
+
+// RuleRequest 创建规则请求
+type RuleRequest struct {
+	FolderID          uint     `json:"folder_id" binding:"required"` // 规则所在文件夹ID
+	Name              string   `json:"name" binding:"required"`      // 规则名称
+	Condition         string   `json:"condition"`                    // 条件表达式，为空时总是执行
+	IncludeSubfolders bool     `json:"include_subfolders"`           // 是否对子文件夹中的文件生效
+	IsActive          *bool    `json:"is_active"`                    // 是否启用，默认启用
+	Action            string   `json:"action" binding:"required"`    // 动作类型(webhook/tag/move)
+	WebhookURL        string   `json:"webhook_url"`                  // Webhook地址(webhook动作)
+	WebhookSecret     string   `json:"webhook_secret"`               // Webhook签名密钥(webhook动作，可选)
+	Tags              []string `json:"tags"`                         // 要添加的标签(tag动作)
+	TargetFolderID    *uint    `json:"target_folder_id"`             // 目标文件夹ID(move动作)，为空表示根目录
+}
+
+// RuleUpdate 更新规则请求，为nil的字段保持不变
+//
+// 动作类型不可修改；修改动作参数时只校验当前动作需要的字段
+type RuleUpdate struct {
+	Name              *string   `json:"name"`               // 规则名称
+	Condition         *string   `json:"condition"`          // 条件表达式
+	IncludeSubfolders *bool     `json:"include_subfolders"` // 是否对子文件夹中的文件生效
+	IsActive          *bool     `json:"is_active"`          // 是否启用
+	WebhookURL        *string   `json:"webhook_url"`        // Webhook地址
+	WebhookSecret     *string   `json:"webhook_secret"`     // Webhook签名密钥，空字符串表示取消签名
+	Tags              *[]string `json:"tags"`               // 要添加的标签
+	TargetFolderID    *uint     `json:"target_folder_id"`   // 目标文件夹ID
+	MoveToRoot        bool      `json:"move_to_root"`       // 为true时移动到根目录(忽略target_folder_id)
+}
+
+// ruleService 文件夹自动化规则管理服务实现
+type ruleService struct {
+	rules   filerepo.FolderRuleRepository
+	files   FileReader
+	options RuleOptions
+	logger  *zap.Logger
+	now     func() time.Time
+}
+
+// NewRuleService 创建文件夹自动化规则管理服务实例
+func NewRuleService(rules filerepo.FolderRuleRepository, files FileReader, options RuleOptions, logger *zap.Logger) RuleService {
+	if options.MaxRulesPerUser <= 0 {
+		options.MaxRulesPerUser = DefaultMaxRulesPerUser
+	}
+	if options.LogRetention <= 0 {
+		options.LogRetention = DefaultLogRetention
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &ruleService{
+		rules:   rules,
+		files:   files,
+		options: options,
+		logger:  logger,
+		now:     time.Now,
+	}
+}
+
+// CreateRule 在用户的文件夹上创建规则
+func (s *ruleService) CreateRule(ctx context.Context, userID uint, req *RuleRequest) (*models.FolderRule, error) {
+	if userID == 0 || req == nil || req.FolderID == 0 {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "用户ID和文件夹ID不能为空")
+	}
+	if _, err := s.getOwnedFolder(ctx, userID, req.FolderID, "文件夹"); err != nil {
+		return nil, err
+	}
+
+	count, err := s.rules.CountByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("统计规则数失败: %w", err)
+	}
+	if count >= int64(s.options.MaxRulesPerUser) {
+		return nil, pkgErrors.WrapErrorf(pkgErrors.ErrQuotaExceeded, "最多只能创建 %d 条规则", s.options.MaxRulesPerUser)
+	}
+
+	rule := &models.FolderRule{
+		UserID:            userID,
+		FolderID:          req.FolderID,
+		Name:              req.Name,
+		Event:             models.RuleEventFileUploaded,
+		Condition:         strings.TrimSpace(req.Condition),
+		IncludeSubfolders: req.IncludeSubfolders,
+		IsActive:          req.IsActive == nil || *req.IsActive,
+		Action:            req.Action,
+	}
+	switch req.Action {
+	case models.RuleActionWebhook:
+		rule.WebhookURL = strings.TrimSpace(req.WebhookURL)
+		rule.WebhookSecret = req.WebhookSecret
+	case models.RuleActionTag:
+		rule.Tags = joinTags(req.Tags)
+	case models.RuleActionMove:
+		rule.TargetFolderID = req.TargetFolderID
+	default:
+		return nil, pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "不支持的动作类型 %q", req.Action)
+	}
+	if err := s.validate(ctx, rule); err != nil {
+		return nil, err
+	}
+
+	if err := s.rules.Create(ctx, rule); err != nil {
+		return nil, fmt.Errorf("创建规则失败: %w", err)
+	}
+	rule.WebhookSigned = rule.WebhookSecret != ""
+
+	s.logger.Info("Folder rule created",
+		zap.Uint("user_id", userID),
+		zap.Uint("rule_id", rule.ID),
+		zap.Uint("folder_id", rule.FolderID),
+		zap.String("action", rule.Action))
+	return rule, nil
+}
+
+// ListRules 列出用户的规则，folderID 不为nil时只列出该文件夹上的规则
+func (s *ruleService) ListRules(ctx context.Context, userID uint, folderID *uint) ([]*models.FolderRule, error) {
+	if userID == 0 {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "用户ID不能为空")
+	}
+	rules, err := s.rules.ListByUser(ctx, userID, folderID)
+	if err != nil {
+		return nil, fmt.Errorf("获取规则列表失败: %w", err)
+	}
+	return rules, nil
+}
+
+// UpdateRule 更新规则，重新校验更新后的完整规则
+func (s *ruleService) UpdateRule(ctx context.Context, userID, ruleID uint, req *RuleUpdate) (*models.FolderRule, error) {
+	if req == nil {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "更新内容不能为空")
+	}
+	rule, err := s.getOwnedRule(ctx, userID, ruleID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		rule.Name = *req.Name
+	}
+	if req.Condition != nil {
+		rule.Condition = strings.TrimSpace(*req.Condition)
+	}
+	if req.IncludeSubfolders != nil {
+		rule.IncludeSubfolders = *req.IncludeSubfolders
+	}
+	if req.IsActive != nil {
+		rule.IsActive = *req.IsActive
+	}
+	switch rule.Action {
+	case models.RuleActionWebhook:
+		if req.WebhookURL != nil {
+			rule.WebhookURL = strings.TrimSpace(*req.WebhookURL)
+		}
+		if req.WebhookSecret != nil {
+			rule.WebhookSecret = *req.WebhookSecret
+		}
+	case models.RuleActionTag:
+		if req.Tags != nil {
+			rule.Tags = joinTags(*req.Tags)
+		}
+	case models.RuleActionMove:
+		if req.MoveToRoot {
+			rule.TargetFolderID = nil
+		} else if req.TargetFolderID != nil {
+			rule.TargetFolderID = req.TargetFolderID
+		}
+	}
+	if err := s.validate(ctx, rule); err != nil {
+		return nil, err
+	}
+
+	if err := s.rules.Update(ctx, rule); err != nil {
+		return nil, fmt.Errorf("更新规则失败: %w", err)
+	}
+	rule.WebhookSigned = rule.WebhookSecret != ""
+	return rule, nil
+}
+
+// DeleteRule 删除规则，执行日志保留到过期清理
+func (s *ruleService) DeleteRule(ctx context.Context, userID, ruleID uint) error {
+	rule, err := s.getOwnedRule(ctx, userID, ruleID)
+	if err != nil {
+		return err
+	}
+	if err := s.rules.Delete(ctx, rule.ID); err != nil {
+		return fmt.Errorf("删除规则失败: %w", err)
+	}
+
+	s.logger.Info("Folder rule deleted",
+		zap.Uint("user_id", userID),
+		zap.Uint("rule_id", rule.ID))
+	return nil
+}
+
+// ListLogs 分页查询规则的执行日志，按时间倒序
+func (s *ruleService) ListLogs(ctx context.Context, userID, ruleID uint, page, pageSize int) ([]*models.FolderRuleLog, int64, error) {
+	rule, err := s.getOwnedRule(ctx, userID, ruleID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > maxRuleLogPageSize {
+		pageSize = maxRuleLogPageSize
+	}
+
+	logs, total, err := s.rules.ListLogs(ctx, rule.ID, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("获取执行日志失败: %w", err)
+	}
+	return logs, total, nil
+}
+
+// PurgeLogs 删除超过保留期的执行日志，每次最多删除limit条，供维护任务调用
+func (s *ruleService) PurgeLogs(ctx context.Context, limit int) (int64, error) {
+	return s.rules.DeleteLogsBefore(ctx, s.now().Add(-s.options.LogRetention), limit)
+}
+
+// validate 校验规则名称、条件表达式和当前动作的参数
+func (s *ruleService) validate(ctx context.Context, rule *models.FolderRule) error {
+	rule.Name = strings.TrimSpace(rule.Name)
+	if rule.Name == "" || utf8.RuneCountInString(rule.Name) > maxRuleNameLength {
+		return pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "规则名称长度必须在1-%d个字符之间", maxRuleNameLength)
+	}
+	if _, err := ParseCondition(rule.Condition); err != nil {
+		return pkgErrors.WrapError(pkgErrors.ErrInvalidInput, err.Error())
+	}
+
+	switch rule.Action {
+	case models.RuleActionWebhook:
+		return validateWebhook(rule.WebhookURL, rule.WebhookSecret)
+	case models.RuleActionTag:
+		tags := splitTags(rule.Tags)
+		if len(tags) == 0 || len(tags) > maxRuleTags {
+			return pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "标签数量必须在1-%d个之间", maxRuleTags)
+		}
+		for _, tag := range tags {
+			if utf8.RuneCountInString(tag) > maxRuleTagLength {
+				return pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "标签不能超过%d个字符", maxRuleTagLength)
+			}
+		}
+		return nil
+	case models.RuleActionMove:
+		if rule.TargetFolderID == nil {
+			return nil
+		}
+		if *rule.TargetFolderID == rule.FolderID {
+			return pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "目标文件夹不能是规则所在的文件夹")
+		}
+		_, err := s.getOwnedFolder(ctx, rule.UserID, *rule.TargetFolderID, "目标文件夹")
+		return err
+	default:
+		return pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "不支持的动作类型 %q", rule.Action)
+	}
+}
+
+// validateWebhook 校验Webhook地址和签名密钥，只允许http和https地址
+//
+// 内网地址在发送时按解析出的IP拦截，这里只检查格式
+func validateWebhook(rawURL, secret string) error {
+	if rawURL == "" || len(rawURL) > maxWebhookURLLength {
+		return pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "Webhook地址长度必须在1-%d个字符之间", maxWebhookURLLength)
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Hostname() == "" {
+		return pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "Webhook地址必须是有效的http或https地址")
+	}
+	if parsed.User != nil {
+		return pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "Webhook地址不能包含用户名和密码，请使用签名密钥")
+	}
+	if len(secret) > maxWebhookSecretLength {
+		return pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "签名密钥不能超过%d个字符", maxWebhookSecretLength)
+	}
+	return nil
+}
+
+// getOwnedRule 获取用户自己的规则
+func (s *ruleService) getOwnedRule(ctx context.Context, userID, ruleID uint) (*models.FolderRule, error) {
+	if userID == 0 || ruleID == 0 {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "用户ID和规则ID不能为空")
+	}
+	rule, err := s.rules.GetByID(ctx, ruleID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "规则不存在")
+		}
+		return nil, fmt.Errorf("获取规则失败: %w", err)
+	}
+	// 不区分不存在和无权访问，避免泄露其他用户的规则ID
+	if rule.UserID != userID {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "规则不存在")
+	}
+	return rule, nil
+}
+
+// getOwnedFolder 获取用户自己的有效文件夹，label用于错误提示
+func (s *ruleService) getOwnedFolder(ctx context.Context, userID, folderID uint, label string) (*models.File, error) {
+	folder, err := s.files.GetByID(ctx, folderID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, label+"不存在")
+		}
+		return nil, fmt.Errorf("获取%s失败: %w", label, err)
+	}
+	if folder.UserID != userID {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, "无权访问"+label)
+	}
+	if !folder.IsFolder || !folder.IsActive() {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrInvalidInput, label+"必须是有效的文件夹")
+	}
+	return folder, nil
+}
+
+// joinTags 去重并拼接标签，保持原有顺序
+func joinTags(tags []string) string {
+	seen := make(map[string]bool, len(tags))
+	var result []string
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		key := strings.ToLower(tag)
+		if tag == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, tag)
+	}
+	return strings.Join(result, ",")
+}
@@ -0,0 +1,340 @@
+package automation
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/repository/models"
+)
+
+// memoryRules 内存规则仓储
+type memoryRules struct {
+	rules  map[uint]*models.FolderRule
+	logs   []*models.FolderRuleLog
+	nextID uint
+}
+
+func newMemoryRules() *memoryRules {
+	return &memoryRules{rules: map[uint]*models.FolderRule{}}
+}
+
+func (m *memoryRules) Create(_ context.Context, rule *models.FolderRule) error {
+	m.nextID++
+	rule.ID = m.nextID
+	copied := *rule
+	m.rules[rule.ID] = &copied
+	return nil
+}
+
+func (m *memoryRules) GetByID(_ context.Context, id uint) (*models.FolderRule, error) {
+	rule, ok := m.rules[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *rule
+	copied.WebhookSigned = copied.WebhookSecret != ""
+	return &copied, nil
+}
+
+func (m *memoryRules) ListByUser(_ context.Context, userID uint, folderID *uint) ([]*models.FolderRule, error) {
+	var rules []*models.FolderRule
+	for _, rule := range m.sorted() {
+		if rule.UserID == userID && (folderID == nil || rule.FolderID == *folderID) {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+func (m *memoryRules) CountByUser(ctx context.Context, userID uint) (int64, error) {
+	rules, _ := m.ListByUser(ctx, userID, nil)
+	return int64(len(rules)), nil
+}
+
+func (m *memoryRules) Update(_ context.Context, rule *models.FolderRule) error {
+	copied := *rule
+	m.rules[rule.ID] = &copied
+	return nil
+}
+
+func (m *memoryRules) Delete(_ context.Context, id uint) error {
+	delete(m.rules, id)
+	return nil
+}
+
+func (m *memoryRules) ListActiveByFolders(_ context.Context, userID uint, folderIDs []uint) ([]*models.FolderRule, error) {
+	var rules []*models.FolderRule
+	for _, rule := range m.sorted() {
+		if rule.UserID != userID || !rule.IsActive {
+			continue
+		}
+		for _, id := range folderIDs {
+			if rule.FolderID == id {
+				rules = append(rules, rule)
+				break
+			}
+		}
+	}
+	return rules, nil
+}
+
+func (m *memoryRules) RecordExecution(_ context.Context, id uint, status string, at time.Time) error {
+	rule := m.rules[id]
+	rule.TriggerCount++
+	if status == models.RuleLogStatusFailed {
+		rule.FailureCount++
+	}
+	rule.LastStatus = status
+	rule.LastTriggeredAt = &at
+	return nil
+}
+
+func (m *memoryRules) CreateLog(_ context.Context, log *models.FolderRuleLog) error {
+	log.ID = uint(len(m.logs) + 1)
+	m.logs = append(m.logs, log)
+	return nil
+}
+
+func (m *memoryRules) ListLogs(_ context.Context, ruleID uint, limit, offset int) ([]*models.FolderRuleLog, int64, error) {
+	var logs []*models.FolderRuleLog
+	for i := len(m.logs) - 1; i >= 0; i-- {
+		if m.logs[i].RuleID == ruleID {
+			logs = append(logs, m.logs[i])
+		}
+	}
+	total := int64(len(logs))
+	if offset >= len(logs) {
+		return nil, total, nil
+	}
+	return logs[offset:min(offset+limit, len(logs))], total, nil
+}
+
+func (m *memoryRules) DeleteLogsBefore(_ context.Context, before time.Time, limit int) (int64, error) {
+	var kept []*models.FolderRuleLog
+	var deleted int64
+	for _, log := range m.logs {
+		if log.CreatedAt.Before(before) && deleted < int64(limit) {
+			deleted++
+			continue
+		}
+		kept = append(kept, log)
+	}
+	m.logs = kept
+	return deleted, nil
+}
+
+func (m *memoryRules) sorted() []*models.FolderRule {
+	rules := make([]*models.FolderRule, 0, len(m.rules))
+	for _, rule := range m.rules {
+		rules = append(rules, rule)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+	return rules
+}
+
+// memoryFiles 内存文件仓储
+type memoryFiles struct {
+	files map[uint]*models.File
+}
+
+func newMemoryFiles(files ...*models.File) *memoryFiles {
+	m := &memoryFiles{files: map[uint]*models.File{}}
+	for _, f := range files {
+		m.files[f.ID] = f
+	}
+	return m
+}
+
+func (m *memoryFiles) GetByID(_ context.Context, id uint) (*models.File, error) {
+	f, ok := m.files[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *f
+	return &copied, nil
+}
+
+func (m *memoryFiles) UpdateTags(_ context.Context, id uint, tags *string) error {
+	m.files[id].Tags = tags
+	return nil
+}
+
+func testFile(id, userID uint, parentID *uint, path, name string, isFolder bool) *models.File {
+	f := &models.File{UserID: userID, ParentID: parentID, Path: path, Name: name, IsFolder: isFolder, Status: "active"}
+	f.ID = id
+	return f
+}
+
+func uintPtr(v uint) *uint {
+	return &v
+}
+
+// newRuleFixture 用户1的文件夹 /收件箱(10)、/收件箱/子目录(11)、/归档(12)，用户2的文件夹 /其他(20)
+func newRuleFixture() (*ruleService, *memoryRules, *memoryFiles) {
+	files := newMemoryFiles(
+		testFile(10, 1, nil, "/", "收件箱", true),
+		testFile(11, 1, uintPtr(10), "/收件箱", "子目录", true),
+		testFile(12, 1, nil, "/", "归档", true),
+		testFile(13, 1, uintPtr(10), "/收件箱", "a.txt", false),
+		testFile(20, 2, nil, "/", "其他", true),
+	)
+	rules := newMemoryRules()
+	service := NewRuleService(rules, files, RuleOptions{MaxRulesPerUser: 3}, nil).(*ruleService)
+	return service, rules, files
+}
+
+func TestRuleServiceCreateRule(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("webhook", func(t *testing.T) {
+		service, rules, _ := newRuleFixture()
+		rule, err := service.CreateRule(ctx, 1, &RuleRequest{
+			FolderID:      10,
+			Name:          " 通知 ",
+			Condition:     `ext == "pdf"`,
+			Action:        models.RuleActionWebhook,
+			WebhookURL:    "https://hooks.example.com/upload",
+			WebhookSecret: "s3cret",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "通知", rule.Name)
+		assert.True(t, rule.IsActive)
+		assert.True(t, rule.WebhookSigned)
+		assert.Equal(t, models.RuleEventFileUploaded, rule.Event)
+		assert.Len(t, rules.rules, 1)
+	})
+
+	t.Run("tag deduplicates", func(t *testing.T) {
+		service, _, _ := newRuleFixture()
+		rule, err := service.CreateRule(ctx, 1, &RuleRequest{
+			FolderID: 10, Name: "标记", Action: models.RuleActionTag, Tags: []string{"发票", " 发票", "Q1", "q1", ""},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "发票,Q1", rule.Tags)
+	})
+
+	t.Run("move to root", func(t *testing.T) {
+		service, _, _ := newRuleFixture()
+		rule, err := service.CreateRule(ctx, 1, &RuleRequest{FolderID: 10, Name: "移出", Action: models.RuleActionMove})
+		require.NoError(t, err)
+		assert.Nil(t, rule.TargetFolderID)
+	})
+
+	tests := []struct {
+		name  string
+		req   *RuleRequest
+		check func(error) bool
+	}{
+		{"missing folder", &RuleRequest{Name: "x", Action: models.RuleActionMove}, pkgErrors.IsValidationError},
+		{"folder not found", &RuleRequest{FolderID: 99, Name: "x", Action: models.RuleActionMove}, pkgErrors.IsNotFoundError},
+		{"other user's folder", &RuleRequest{FolderID: 20, Name: "x", Action: models.RuleActionMove}, pkgErrors.IsPermissionError},
+		{"not a folder", &RuleRequest{FolderID: 13, Name: "x", Action: models.RuleActionMove}, pkgErrors.IsValidationError},
+		{"empty name", &RuleRequest{FolderID: 10, Name: " ", Action: models.RuleActionMove}, pkgErrors.IsValidationError},
+		{"bad condition", &RuleRequest{FolderID: 10, Name: "x", Condition: `size > "1"`, Action: models.RuleActionMove}, pkgErrors.IsValidationError},
+		{"unknown action", &RuleRequest{FolderID: 10, Name: "x", Action: "delete"}, pkgErrors.IsValidationError},
+		{"webhook scheme", &RuleRequest{FolderID: 10, Name: "x", Action: models.RuleActionWebhook, WebhookURL: "ftp://example.com"}, pkgErrors.IsValidationError},
+		{"webhook credentials", &RuleRequest{FolderID: 10, Name: "x", Action: models.RuleActionWebhook, WebhookURL: "https://u:p@example.com"}, pkgErrors.IsValidationError},
+		{"no tags", &RuleRequest{FolderID: 10, Name: "x", Action: models.RuleActionTag, Tags: []string{" "}}, pkgErrors.IsValidationError},
+		{"move into itself", &RuleRequest{FolderID: 10, Name: "x", Action: models.RuleActionMove, TargetFolderID: uintPtr(10)}, pkgErrors.IsValidationError},
+		{"move to other user's folder", &RuleRequest{FolderID: 10, Name: "x", Action: models.RuleActionMove, TargetFolderID: uintPtr(20)}, pkgErrors.IsPermissionError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, rules, _ := newRuleFixture()
+			_, err := service.CreateRule(ctx, 1, tt.req)
+			require.Error(t, err)
+			assert.True(t, tt.check(err), err.Error())
+			assert.Empty(t, rules.rules)
+		})
+	}
+
+	t.Run("per user limit", func(t *testing.T) {
+		service, _, _ := newRuleFixture()
+		for i := 0; i < 3; i++ {
+			_, err := service.CreateRule(ctx, 1, &RuleRequest{FolderID: 10, Name: "x", Action: models.RuleActionMove})
+			require.NoError(t, err)
+		}
+		_, err := service.CreateRule(ctx, 1, &RuleRequest{FolderID: 10, Name: "x", Action: models.RuleActionMove})
+		assert.ErrorIs(t, err, pkgErrors.ErrQuotaExceeded)
+	})
+}
+
+func TestRuleServiceUpdateRule(t *testing.T) {
+	ctx := context.Background()
+	service, rules, _ := newRuleFixture()
+	rule, err := service.CreateRule(ctx, 1, &RuleRequest{
+		FolderID: 10, Name: "归档", Action: models.RuleActionMove, TargetFolderID: uintPtr(12),
+	})
+	require.NoError(t, err)
+
+	t.Run("partial update", func(t *testing.T) {
+		name := "归档PDF"
+		condition := `ext == "pdf"`
+		active := false
+		updated, err := service.UpdateRule(ctx, 1, rule.ID, &RuleUpdate{Name: &name, Condition: &condition, IsActive: &active})
+		require.NoError(t, err)
+		assert.Equal(t, "归档PDF", updated.Name)
+		assert.Equal(t, condition, rules.rules[rule.ID].Condition)
+		assert.False(t, rules.rules[rule.ID].IsActive)
+		assert.Equal(t, uintPtr(12), rules.rules[rule.ID].TargetFolderID)
+	})
+
+	t.Run("move to root", func(t *testing.T) {
+		_, err := service.UpdateRule(ctx, 1, rule.ID, &RuleUpdate{MoveToRoot: true})
+		require.NoError(t, err)
+		assert.Nil(t, rules.rules[rule.ID].TargetFolderID)
+	})
+
+	t.Run("invalid condition rejected", func(t *testing.T) {
+		condition := `ext ==`
+		_, err := service.UpdateRule(ctx, 1, rule.ID, &RuleUpdate{Condition: &condition})
+		assert.True(t, pkgErrors.IsValidationError(err))
+		assert.Equal(t, `ext == "pdf"`, rules.rules[rule.ID].Condition)
+	})
+
+	t.Run("other user sees not found", func(t *testing.T) {
+		_, err := service.UpdateRule(ctx, 2, rule.ID, &RuleUpdate{})
+		assert.True(t, pkgErrors.IsNotFoundError(err))
+		assert.True(t, pkgErrors.IsNotFoundError(service.DeleteRule(ctx, 2, rule.ID)))
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		require.NoError(t, service.DeleteRule(ctx, 1, rule.ID))
+		assert.Empty(t, rules.rules)
+	})
+}
+
+func TestRuleServiceLogs(t *testing.T) {
+	ctx := context.Background()
+	service, rules, _ := newRuleFixture()
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+
+	rule, err := service.CreateRule(ctx, 1, &RuleRequest{FolderID: 10, Name: "x", Action: models.RuleActionMove})
+	require.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		require.NoError(t, rules.CreateLog(ctx, &models.FolderRuleLog{RuleID: rule.ID, CreatedAt: now.AddDate(0, 0, -10*i)}))
+	}
+
+	logs, total, err := service.ListLogs(ctx, 1, rule.ID, 2, 2)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), total)
+	require.Len(t, logs, 2)
+	assert.Equal(t, uint(3), logs[0].ID)
+
+	_, _, err = service.ListLogs(ctx, 2, rule.ID, 1, 10)
+	assert.True(t, pkgErrors.IsNotFoundError(err))
+
+	// 默认保留30天：40天前的日志被删除
+	deleted, err := service.PurgeLogs(ctx, 100)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+	assert.Len(t, rules.logs, 4)
+}
@@ -0,0 +1,215 @@
+package featureflag
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	basemodels "cloudpan/internal/pkg/database/models"
+	"cloudpan/internal/repository/models"
+)
+
+// Bucket 计算用户在特性上的灰度分桶(0 到 BucketCount-1)
+//
+// 分桶只取决于特性键和用户ID：同一特性提高百分比时已命中的用户保持命中，
+// 不同特性之间的分桶相互独立
+func Bucket(key string, userID uint) int {
+	sum := sha256.Sum256([]byte(key + ":" + strconv.FormatUint(uint64(userID), 10)))
+	return int(binary.BigEndian.Uint32(sum[:4]) % BucketCount)
+}
+
+// EvaluateFlag 对单个特性标记进行评估
+//
+// 判定顺序：总开关 -> 生效时间 -> 匿名默认值 -> 指定用户 -> 人群定向 -> 百分比灰度
+func EvaluateFlag(flag *models.FeatureFlag, subject Subject, now time.Time) *Evaluation {
+	result := &Evaluation{Key: flag.Key}
+
+	if !flag.IsEnabled {
+		result.Reason = ReasonDisabled
+		return result
+	}
+	if (flag.StartTime != nil && now.Before(*flag.StartTime)) || (flag.EndTime != nil && now.After(*flag.EndTime)) {
+		result.Reason = ReasonOutsideWindow
+		return result
+	}
+
+	if subject.UserID == 0 {
+		result.Enabled = flag.IsDefault
+		result.Reason = ReasonDefault
+		return result
+	}
+
+	if flag.TargetUsers != nil && containsUser(parseList(*flag.TargetUsers), subject) {
+		result.Enabled = true
+		result.Reason = ReasonTargetUser
+		return result
+	}
+
+	if !matchCohort(flag.Conditions, subject) {
+		result.Reason = ReasonCohortMismatch
+		return result
+	}
+
+	if flag.TargetPercent == nil {
+		result.Enabled = true
+		result.Reason = ReasonEnabled
+		return result
+	}
+
+	bucket := Bucket(flag.Key, subject.UserID)
+	result.Bucket = &bucket
+	if bucket < clampPercent(*flag.TargetPercent)*BucketCount/100 {
+		result.Enabled = true
+		result.Reason = ReasonRollout
+	} else {
+		result.Reason = ReasonRolloutExcluded
+	}
+	return result
+}
+
+// SubjectFromUser 根据用户信息构建评估对象
+func SubjectFromUser(user *models.User) Subject {
+	subject := Subject{
+		UserID:       user.ID,
+		UserUUID:     user.UUID,
+		Plan:         DefaultPlan,
+		RegisteredAt: user.CreatedAt,
+	}
+	if user.Profile != nil {
+		if plan, ok := (*user.Profile)[ProfileKeyPlan].(string); ok && plan != "" {
+			subject.Plan = plan
+		}
+		if tenant, ok := (*user.Profile)[ProfileKeyTenant].(string); ok {
+			subject.Tenant = tenant
+		}
+	}
+	return subject
+}
+
+// matchCohort 检查评估对象是否属于条件描述的人群，未配置的条件视为满足
+//
+// 条件格式错误时视为不满足，避免配置错误导致特性意外放量
+func matchCohort(conditions *basemodels.JSONMap, subject Subject) bool {
+	if conditions == nil {
+		return true
+	}
+	c := *conditions
+
+	if value, ok := c[ConditionPlans]; ok {
+		plans, valid := toStringList(value)
+		if !valid || !containsFold(plans, subject.Plan) {
+			return false
+		}
+	}
+	if value, ok := c[ConditionTenants]; ok {
+		tenants, valid := toStringList(value)
+		if !valid || subject.Tenant == "" || !containsFold(tenants, subject.Tenant) {
+			return false
+		}
+	}
+	if value, ok := c[ConditionRegisteredAfter]; ok {
+		after, err := parseConditionTime(value)
+		if err != nil || subject.RegisteredAt.Before(after) {
+			return false
+		}
+	}
+	if value, ok := c[ConditionRegisteredBefore]; ok {
+		before, err := parseConditionTime(value)
+		if err != nil || !subject.RegisteredAt.Before(before) {
+			return false
+		}
+	}
+	return true
+}
+
+// containsUser 检查列表中是否包含用户ID或UUID
+func containsUser(items []string, subject Subject) bool {
+	id := strconv.FormatUint(uint64(subject.UserID), 10)
+	for _, item := range items {
+		if item == id || (subject.UserUUID != "" && item == subject.UserUUID) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseList 解析列表字段，支持JSON数组或逗号分隔
+func parseList(value string) []string {
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "[") {
+		var raw []interface{}
+		if err := json.Unmarshal([]byte(value), &raw); err == nil {
+			items := make([]string, 0, len(raw))
+			for _, item := range raw {
+				items = append(items, strings.TrimSpace(fmt.Sprint(item)))
+			}
+			return items
+		}
+	}
+
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// toStringList 将JSON条件值转换为字符串列表
+func toStringList(value interface{}) ([]string, bool) {
+	switch v := value.(type) {
+	case string:
+		return parseList(v), true
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, false
+			}
+			items = append(items, s)
+		}
+		return items, true
+	case []string:
+		return v, true
+	}
+	return nil, false
+}
+
+// containsFold 忽略大小写检查列表是否包含值
+func containsFold(items []string, value string) bool {
+	for _, item := range items {
+		if strings.EqualFold(strings.TrimSpace(item), value) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseConditionTime 解析时间条件，支持RFC3339和日期格式
+func parseConditionTime(value interface{}) (time.Time, error) {
+	s, ok := value.(string)
+	if !ok {
+		return time.Time{}, fmt.Errorf("时间条件必须为字符串")
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", s)
+}
+
+// clampPercent 将百分比限制在0-100之间
+func clampPercent(percent int) int {
+	if percent < 0 {
+		return 0
+	}
+	if percent > 100 {
+		return 100
+	}
+	return percent
+}
@@ -0,0 +1,88 @@
+package featureflag
+
+import (
+	"context"
+	"time"
+
+	"cloudpan/internal/repository/models"
+)
+
+// FeatureFlagService 特性开关评估服务接口
+//
+// 支持总开关、生效时间窗口、指定用户、人群定向(套餐、注册时间、租户)和按百分比灰度。
+// 百分比灰度按 特性键+用户ID 确定性分桶，同一用户对同一特性的结果始终一致，
+// 前端通过评估接口获取结果，保证前后端判断一致
+//
+// 使用示例：
+//
+//	service := NewFeatureFlagService(flagRepo, userRepo, logger)
+//	enabled := service.IsEnabled(ctx, "new_uploader", userID)
+//	evaluations, err := service.EvaluateAll(ctx, userID)
+type FeatureFlagService interface {
+	Evaluate(ctx context.Context, key string, userID uint) (*Evaluation, error)
+	EvaluateAll(ctx context.Context, userID uint) ([]*Evaluation, error)
+	IsEnabled(ctx context.Context, key string, userID uint) bool
+	Refresh()
+}
+
+// FlagReader 读取特性标记，由特性标记仓储实现
+type FlagReader interface {
+	ListAll(ctx context.Context) ([]*models.FeatureFlag, error)
+}
+
+// UserReader 读取用户信息，由用户仓储实现
+type UserReader interface {
+	GetByID(ctx context.Context, id uint) (*models.User, error)
+}
+
+// Subject 评估对象(用户及其人群属性)
+type Subject struct {
+	UserID       uint      `json:"user_id"`       // 用户ID(0表示匿名)
+	UserUUID     string    `json:"user_uuid"`     // 用户UUID
+	Plan         string    `json:"plan"`          // 套餐
+	Tenant       string    `json:"tenant"`        // 租户
+	RegisteredAt time.Time `json:"registered_at"` // 注册时间
+}
+
+// Evaluation 特性评估结果
+type Evaluation struct {
+	Key     string `json:"key"`              // 特性键
+	Enabled bool   `json:"enabled"`          // 是否启用
+	Reason  string `json:"reason"`           // 判定原因
+	Bucket  *int   `json:"bucket,omitempty"` // 灰度分桶(0-9999，仅百分比灰度时返回)
+}
+
+// 判定原因
+const (
+	ReasonNotFound        = "not_found"        // 特性不存在
+	ReasonDisabled        = "disabled"         // 总开关关闭
+	ReasonOutsideWindow   = "outside_window"   // 不在生效时间内
+	ReasonDefault         = "default"          // 匿名用户使用默认值
+	ReasonTargetUser      = "target_user"      // 指定用户
+	ReasonCohortMismatch  = "cohort_mismatch"  // 不属于目标人群
+	ReasonRollout         = "rollout"          // 命中百分比灰度
+	ReasonRolloutExcluded = "rollout_excluded" // 未命中百分比灰度
+	ReasonEnabled         = "enabled"          // 全量启用
+)
+
+// 人群定向条件键(存储在特性标记的 conditions 字段中)
+//
+//	{"plans": ["pro"], "tenants": ["acme"], "registered_after": "2024-01-01", "registered_before": "2024-06-30T00:00:00Z"}
+const (
+	ConditionPlans            = "plans"
+	ConditionTenants          = "tenants"
+	ConditionRegisteredAfter  = "registered_after"
+	ConditionRegisteredBefore = "registered_before"
+)
+
+// 用户资料中的人群属性键
+const (
+	ProfileKeyPlan   = "plan"
+	ProfileKeyTenant = "tenant"
+)
+
+// DefaultPlan 未设置套餐的用户所属套餐
+const DefaultPlan = "free"
+
+// BucketCount 灰度分桶总数，百分比精度为0.01%
+const BucketCount = 10000
@@ -0,0 +1,145 @@
+package featureflag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/repository/models"
+)
+
+// flagCacheTTL 特性标记本地缓存时间，管理员修改后最多延迟该时间生效
+const flagCacheTTL = 30 * time.Second
+
+// featureFlagService 特性开关评估服务实现
+type featureFlagService struct {
+	flagRepo FlagReader
+	userRepo UserReader
+	logger   *zap.Logger
+	now      func() time.Time
+
+	mu       sync.RWMutex
+	flags    map[string]*models.FeatureFlag
+	loadedAt time.Time
+}
+
+// NewFeatureFlagService 创建特性开关评估服务实例
+func NewFeatureFlagService(flagRepo FlagReader, userRepo UserReader, logger *zap.Logger) FeatureFlagService {
+	return &featureFlagService{
+		flagRepo: flagRepo,
+		userRepo: userRepo,
+		logger:   logger,
+		now:      time.Now,
+	}
+}
+
+// Evaluate 评估单个特性对用户是否启用
+func (s *featureFlagService) Evaluate(ctx context.Context, key string, userID uint) (*Evaluation, error) {
+	if key == "" {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "特性键不能为空")
+	}
+
+	flags, err := s.loadFlags(ctx)
+	if err != nil {
+		return nil, err
+	}
+	flag, ok := flags[key]
+	if !ok {
+		return &Evaluation{Key: key, Reason: ReasonNotFound}, nil
+	}
+
+	subject, err := s.subject(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return EvaluateFlag(flag, subject, s.now()), nil
+}
+
+// EvaluateAll 评估全部特性，结果按特性键排序
+func (s *featureFlagService) EvaluateAll(ctx context.Context, userID uint) ([]*Evaluation, error) {
+	flags, err := s.loadFlags(ctx)
+	if err != nil {
+		return nil, err
+	}
+	subject, err := s.subject(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	evaluations := make([]*Evaluation, 0, len(flags))
+	for _, flag := range flags {
+		evaluations = append(evaluations, EvaluateFlag(flag, subject, now))
+	}
+	sort.Slice(evaluations, func(i, j int) bool { return evaluations[i].Key < evaluations[j].Key })
+	return evaluations, nil
+}
+
+// IsEnabled 判断特性对用户是否启用，评估失败时视为未启用
+func (s *featureFlagService) IsEnabled(ctx context.Context, key string, userID uint) bool {
+	evaluation, err := s.Evaluate(ctx, key, userID)
+	if err != nil {
+		s.logger.Warn("Failed to evaluate feature flag",
+			zap.String("key", key),
+			zap.Uint("user_id", userID),
+			zap.Error(err))
+		return false
+	}
+	return evaluation.Enabled
+}
+
+// Refresh 清除本地缓存，下次评估时重新加载特性标记
+func (s *featureFlagService) Refresh() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flags = nil
+}
+
+// loadFlags 获取特性标记，本地缓存过期时从数据库重新加载
+func (s *featureFlagService) loadFlags(ctx context.Context) (map[string]*models.FeatureFlag, error) {
+	s.mu.RLock()
+	if s.flags != nil && s.now().Sub(s.loadedAt) < flagCacheTTL {
+		flags := s.flags
+		s.mu.RUnlock()
+		return flags, nil
+	}
+	s.mu.RUnlock()
+
+	list, err := s.flagRepo.ListAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("加载特性标记失败: %w", err)
+	}
+	flags := make(map[string]*models.FeatureFlag, len(list))
+	for _, flag := range list {
+		flags[flag.Key] = flag
+	}
+
+	s.mu.Lock()
+	s.flags = flags
+	s.loadedAt = s.now()
+	s.mu.Unlock()
+	return flags, nil
+}
+
+// subject 构建用户的评估对象，userID为0时为匿名对象
+func (s *featureFlagService) subject(ctx context.Context, userID uint) (Subject, error) {
+	if userID == 0 {
+		return Subject{}, nil
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return Subject{}, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "用户不存在")
+		}
+		return Subject{}, fmt.Errorf("获取用户失败: %w", err)
+	}
+	return SubjectFromUser(user), nil
+}
@@ -0,0 +1,223 @@
+package featureflag
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	basemodels "cloudpan/internal/pkg/database/models"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/repository/models"
+)
+
+// MockFlagReader 模拟特性标记读取
+type MockFlagReader struct {
+	mock.Mock
+}
+
+func (m *MockFlagReader) ListAll(ctx context.Context) ([]*models.FeatureFlag, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.FeatureFlag), args.Error(1)
+}
+
+// MockUserReader 模拟用户读取
+type MockUserReader struct {
+	mock.Mock
+}
+
+func (m *MockUserReader) GetByID(ctx context.Context, id uint) (*models.User, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func intPtr(v int) *int              { return &v }
+func strPtr(v string) *string        { return &v }
+func timePtr(v time.Time) *time.Time { return &v }
+
+func TestBucket(t *testing.T) {
+	t.Run("确定性", func(t *testing.T) {
+		assert.Equal(t, Bucket("new_uploader", 42), Bucket("new_uploader", 42))
+	})
+
+	t.Run("分布均匀", func(t *testing.T) {
+		hits := 0
+		for id := uint(1); id <= 10000; id++ {
+			bucket := Bucket("new_uploader", id)
+			require.True(t, bucket >= 0 && bucket < BucketCount)
+			if bucket < 20*BucketCount/100 {
+				hits++
+			}
+		}
+		assert.InDelta(t, 2000, hits, 200)
+	})
+
+	t.Run("提高百分比时已命中用户保持命中", func(t *testing.T) {
+		flag := &models.FeatureFlag{Key: "new_uploader", IsEnabled: true, TargetPercent: intPtr(10)}
+		wider := &models.FeatureFlag{Key: "new_uploader", IsEnabled: true, TargetPercent: intPtr(30)}
+		now := time.Now()
+		for id := uint(1); id <= 1000; id++ {
+			if EvaluateFlag(flag, Subject{UserID: id}, now).Enabled {
+				assert.True(t, EvaluateFlag(wider, Subject{UserID: id}, now).Enabled)
+			}
+		}
+	})
+}
+
+func TestEvaluateFlag(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	subject := Subject{
+		UserID:       7,
+		UserUUID:     "uuid-7",
+		Plan:         "pro",
+		Tenant:       "acme",
+		RegisteredAt: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	tests := []struct {
+		name    string
+		flag    models.FeatureFlag
+		subject Subject
+		enabled bool
+		reason  string
+	}{
+		{"总开关关闭", models.FeatureFlag{IsEnabled: false}, subject, false, ReasonDisabled},
+		{"未到生效时间", models.FeatureFlag{IsEnabled: true, StartTime: timePtr(now.Add(time.Hour))}, subject, false, ReasonOutsideWindow},
+		{"已过期", models.FeatureFlag{IsEnabled: true, EndTime: timePtr(now.Add(-time.Hour))}, subject, false, ReasonOutsideWindow},
+		{"匿名用户使用默认值", models.FeatureFlag{IsEnabled: true, IsDefault: true, TargetPercent: intPtr(0)}, Subject{}, true, ReasonDefault},
+		{"指定用户ID", models.FeatureFlag{IsEnabled: true, TargetUsers: strPtr("3, 7"), TargetPercent: intPtr(0)}, subject, true, ReasonTargetUser},
+		{"指定用户UUID", models.FeatureFlag{IsEnabled: true, TargetUsers: strPtr(`["uuid-7"]`), TargetPercent: intPtr(0)}, subject, true, ReasonTargetUser},
+		{"全量启用", models.FeatureFlag{IsEnabled: true}, subject, true, ReasonEnabled},
+		{"套餐匹配", models.FeatureFlag{IsEnabled: true, Conditions: &basemodels.JSONMap{"plans": []interface{}{"Pro", "team"}}}, subject, true, ReasonEnabled},
+		{"套餐不匹配", models.FeatureFlag{IsEnabled: true, Conditions: &basemodels.JSONMap{"plans": []interface{}{"team"}}}, subject, false, ReasonCohortMismatch},
+		{"租户匹配", models.FeatureFlag{IsEnabled: true, Conditions: &basemodels.JSONMap{"tenants": "acme,globex"}}, subject, true, ReasonEnabled},
+		{"无租户用户", models.FeatureFlag{IsEnabled: true, Conditions: &basemodels.JSONMap{"tenants": "acme"}}, Subject{UserID: 8}, false, ReasonCohortMismatch},
+		{"注册时间之后", models.FeatureFlag{IsEnabled: true, Conditions: &basemodels.JSONMap{"registered_after": "2024-01-01"}}, subject, true, ReasonEnabled},
+		{"注册时间之前", models.FeatureFlag{IsEnabled: true, Conditions: &basemodels.JSONMap{"registered_before": "2024-02-01T00:00:00Z"}}, subject, false, ReasonCohortMismatch},
+		{"条件格式错误", models.FeatureFlag{IsEnabled: true, Conditions: &basemodels.JSONMap{"registered_after": 20240101}}, subject, false, ReasonCohortMismatch},
+		{"全量灰度", models.FeatureFlag{IsEnabled: true, TargetPercent: intPtr(100)}, subject, true, ReasonRollout},
+		{"零灰度", models.FeatureFlag{IsEnabled: true, TargetPercent: intPtr(0)}, subject, false, ReasonRolloutExcluded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.flag.Key = "feature"
+			result := EvaluateFlag(&tt.flag, tt.subject, now)
+			assert.Equal(t, tt.enabled, result.Enabled)
+			assert.Equal(t, tt.reason, result.Reason)
+		})
+	}
+
+	t.Run("灰度结果返回分桶", func(t *testing.T) {
+		flag := &models.FeatureFlag{Key: "feature", IsEnabled: true, TargetPercent: intPtr(50)}
+		result := EvaluateFlag(flag, subject, now)
+		require.NotNil(t, result.Bucket)
+		assert.Equal(t, Bucket("feature", subject.UserID), *result.Bucket)
+		assert.Equal(t, *result.Bucket < 5000, result.Enabled)
+	})
+}
+
+func TestSubjectFromUser(t *testing.T) {
+	user := &models.User{UUID: "uuid-1"}
+	user.ID = 1
+	assert.Equal(t, DefaultPlan, SubjectFromUser(user).Plan)
+
+	user.Profile = &basemodels.JSONMap{"plan": "pro", "tenant": "acme"}
+	subject := SubjectFromUser(user)
+	assert.Equal(t, "pro", subject.Plan)
+	assert.Equal(t, "acme", subject.Tenant)
+	assert.Equal(t, "uuid-1", subject.UserUUID)
+}
+
+func TestFeatureFlagService(t *testing.T) {
+	ctx := context.Background()
+	flags := []*models.FeatureFlag{
+		{Key: "zeta", IsEnabled: true},
+		{Key: "alpha", IsEnabled: true, Conditions: &basemodels.JSONMap{"plans": []interface{}{"pro"}}},
+	}
+
+	t.Run("评估全部特性并缓存", func(t *testing.T) {
+		flagRepo := new(MockFlagReader)
+		userRepo := new(MockUserReader)
+		flagRepo.On("ListAll", ctx).Return(flags, nil).Once()
+		user := &models.User{Profile: &basemodels.JSONMap{"plan": "pro"}}
+		user.ID = 1
+		userRepo.On("GetByID", ctx, uint(1)).Return(user, nil)
+
+		service := NewFeatureFlagService(flagRepo, userRepo, zap.NewNop())
+		evaluations, err := service.EvaluateAll(ctx, 1)
+		require.NoError(t, err)
+		require.Len(t, evaluations, 2)
+		assert.Equal(t, "alpha", evaluations[0].Key)
+		assert.True(t, evaluations[0].Enabled)
+		assert.Equal(t, "zeta", evaluations[1].Key)
+
+		assert.True(t, service.IsEnabled(ctx, "alpha", 1))
+		flagRepo.AssertExpectations(t)
+	})
+
+	t.Run("刷新后重新加载", func(t *testing.T) {
+		flagRepo := new(MockFlagReader)
+		flagRepo.On("ListAll", ctx).Return(flags, nil).Twice()
+
+		service := NewFeatureFlagService(flagRepo, new(MockUserReader), zap.NewNop())
+		_, err := service.EvaluateAll(ctx, 0)
+		require.NoError(t, err)
+		service.Refresh()
+		_, err = service.EvaluateAll(ctx, 0)
+		require.NoError(t, err)
+		flagRepo.AssertExpectations(t)
+	})
+
+	t.Run("特性不存在", func(t *testing.T) {
+		flagRepo := new(MockFlagReader)
+		flagRepo.On("ListAll", ctx).Return(flags, nil)
+
+		service := NewFeatureFlagService(flagRepo, new(MockUserReader), zap.NewNop())
+		evaluation, err := service.Evaluate(ctx, "missing", 1)
+		require.NoError(t, err)
+		assert.False(t, evaluation.Enabled)
+		assert.Equal(t, ReasonNotFound, evaluation.Reason)
+	})
+
+	t.Run("用户不存在", func(t *testing.T) {
+		flagRepo := new(MockFlagReader)
+		userRepo := new(MockUserReader)
+		flagRepo.On("ListAll", ctx).Return(flags, nil)
+		userRepo.On("GetByID", ctx, uint(9)).Return(nil, gorm.ErrRecordNotFound)
+
+		service := NewFeatureFlagService(flagRepo, userRepo, zap.NewNop())
+		_, err := service.Evaluate(ctx, "alpha", 9)
+		assert.True(t, pkgErrors.IsNotFoundError(err))
+		assert.False(t, service.IsEnabled(ctx, "alpha", 9))
+	})
+}
+
+func BenchmarkEvaluateFlag(b *testing.B) {
+	flag := &models.FeatureFlag{
+		Key:           "new_uploader",
+		IsEnabled:     true,
+		TargetUsers:   strPtr("1,2,3"),
+		TargetPercent: intPtr(25),
+		Conditions:    &basemodels.JSONMap{"plans": []interface{}{"free", "pro"}, "registered_after": "2024-01-01"},
+	}
+	subject := Subject{UserID: 42, Plan: "pro", RegisteredAt: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)}
+	now := time.Now()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		EvaluateFlag(flag, subject, now)
+	}
+}
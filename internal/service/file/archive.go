@@ -0,0 +1,302 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/config"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/storage"
+	filerepo "cloudpan/internal/repository/file"
+	"cloudpan/internal/repository/models"
+)
+
+// 归档默认值
+const (
+	DefaultArchiveTransitionAfter = 90 * 24 * time.Hour
+	DefaultArchiveBatchSize       = 100
+	DefaultRestoreDays            = 7
+	DefaultMaxRestoreDays         = 30
+
+	// RestorePollInterval 恢复中建议客户端的轮询间隔，归档恢复通常需要数小时
+	RestorePollInterval = 5 * time.Minute
+)
+
+// ArchiveService 归档存储服务接口
+//
+// 1. 归档策略：定期将长期未访问的对象存储文件转换为归档存储类型
+// 2. 恢复流程：用户对归档文件发起恢复，轮询状态直到恢复完成，恢复副本在保留期内可下载
+//
+// 文件的归档状态为 available(未归档)、archived(已归档)、restoring(恢复中)、
+// restored(已恢复，restored_until之前可读取)，客户端可据此展示对应的界面
+//
+// 使用示例：
+//
+//	service := NewArchiveService(fileRepo, archiver, ArchiveOptionsFromConfig(cfg.Storage.Archive), logger)
+//	report, err := service.TransitionColdFiles(ctx)
+//	status, err := service.RequestRestore(ctx, userID, fileID, 7)
+//	status, err = service.GetArchiveStatus(ctx, userID, fileID)
+type ArchiveService interface {
+	// 归档策略
+	TransitionColdFiles(ctx context.Context) (*TransitionReport, error)
+
+	// 恢复流程
+	RequestRestore(ctx context.Context, userID, fileID uint, days int) (*ArchiveStatus, error)
+	GetArchiveStatus(ctx context.Context, userID, fileID uint) (*ArchiveStatus, error)
+}
+
+// ObjectArchiver 对象归档和恢复，由 storage.S3Archiver 实现
+type ObjectArchiver interface {
+	Archive(ctx context.Context, key, storageClass string) error
+	Restore(ctx context.Context, key string, days int, tier string) error
+	HeadObject(ctx context.Context, key string) (*storage.ObjectInfo, error)
+}
+
+// ArchiveOptions 归档选项
+type ArchiveOptions struct {
+	StorageClass    string        // 归档存储类型
+	TransitionAfter time.Duration // 文件多久未访问后归档
+	MinSize         int64         // 参与归档的最小文件大小
+	BatchSize       int           // 每次最多归档的文件数
+	RestoreDays     int           // 默认恢复保留天数
+	MaxRestoreDays  int           // 最长恢复保留天数
+	RestoreTier     string        // 恢复速度等级
+}
+
+// ArchiveOptionsFromConfig 从归档配置生成选项
+func ArchiveOptionsFromConfig(cfg config.ArchiveConfig) ArchiveOptions {
+	return ArchiveOptions{
+		StorageClass:    cfg.StorageClass,
+		TransitionAfter: cfg.TransitionAfter,
+		MinSize:         cfg.MinSize,
+		BatchSize:       cfg.BatchSize,
+		RestoreDays:     cfg.RestoreDays,
+		MaxRestoreDays:  cfg.MaxRestoreDays,
+		RestoreTier:     cfg.RestoreTier,
+	}
+}
+
+// ArchiveStatus 文件归档状态
+type ArchiveStatus struct {
+	FileID             uint       `json:"file_id"`                        // 文件ID
+	State              string     `json:"state"`                          // available/archived/restoring/restored
+	StorageClass       string     `json:"storage_class"`                  // 存储类型
+	ArchivedAt         *time.Time `json:"archived_at,omitempty"`          // 归档时间
+	RestoreRequestedAt *time.Time `json:"restore_requested_at,omitempty"` // 发起恢复时间
+	RestoredUntil      *time.Time `json:"restored_until,omitempty"`       // 恢复副本可读取的截止时间
+	PollInterval       int        `json:"poll_interval,omitempty"`        // 恢复中时建议的轮询间隔(秒)
+}
+
+// TransitionReport 一次归档扫描的结果
+type TransitionReport struct {
+	Scanned  int `json:"scanned"`  // 候选文件数
+	Archived int `json:"archived"` // 成功归档数
+	Failed   int `json:"failed"`   // 失败数
+}
+
+// archiveService 归档存储服务实现
+type archiveService struct {
+	fileRepo filerepo.FileRepository
+	archiver ObjectArchiver
+	options  ArchiveOptions
+	logger   *zap.Logger
+	now      func() time.Time
+}
+
+// NewArchiveService 创建归档存储服务，未配置的选项使用默认值
+func NewArchiveService(fileRepo filerepo.FileRepository, archiver ObjectArchiver, options ArchiveOptions, logger *zap.Logger) ArchiveService {
+	if options.StorageClass == "" {
+		options.StorageClass = storage.StorageClassGlacier
+	}
+	if options.TransitionAfter <= 0 {
+		options.TransitionAfter = DefaultArchiveTransitionAfter
+	}
+	if options.BatchSize <= 0 {
+		options.BatchSize = DefaultArchiveBatchSize
+	}
+	if options.RestoreDays <= 0 {
+		options.RestoreDays = DefaultRestoreDays
+	}
+	if options.MaxRestoreDays < options.RestoreDays {
+		options.MaxRestoreDays = max(DefaultMaxRestoreDays, options.RestoreDays)
+	}
+	if options.RestoreTier == "" {
+		options.RestoreTier = storage.RestoreTierStandard
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &archiveService{
+		fileRepo: fileRepo,
+		archiver: archiver,
+		options:  options,
+		logger:   logger,
+		now:      time.Now,
+	}
+}
+
+// TransitionColdFiles 归档一批长期未访问的文件
+//
+// 单个文件归档失败不影响其他文件，失败的文件在下次扫描时重试；
+// 超过 storage.MaxCopyObjectSize 的文件无法原地转换，应通过存储桶生命周期规则归档
+func (s *archiveService) TransitionColdFiles(ctx context.Context) (*TransitionReport, error) {
+	now := s.now()
+	candidates, err := s.fileRepo.ListArchiveCandidates(ctx, now.Add(-s.options.TransitionAfter),
+		s.options.MinSize, storage.MaxCopyObjectSize, s.options.BatchSize)
+	if err != nil {
+		return nil, fmt.Errorf("查询归档候选文件失败: %w", err)
+	}
+
+	report := &TransitionReport{Scanned: len(candidates)}
+	for _, file := range candidates {
+		if ctx.Err() != nil {
+			break
+		}
+		if err := s.archiveFile(ctx, file, now); err != nil {
+			report.Failed++
+			s.logger.Warn("Failed to archive file",
+				zap.Uint("file_id", file.ID),
+				zap.Error(err))
+			continue
+		}
+		report.Archived++
+	}
+
+	if report.Scanned > 0 {
+		s.logger.Info("Cold file transition finished",
+			zap.Int("scanned", report.Scanned),
+			zap.Int("archived", report.Archived),
+			zap.Int("failed", report.Failed))
+	}
+	return report, nil
+}
+
+// archiveFile 转换单个文件的存储类型并记录归档状态
+func (s *archiveService) archiveFile(ctx context.Context, file *models.File, now time.Time) error {
+	if file.StoragePath == nil {
+		return fmt.Errorf("文件缺少存储路径")
+	}
+	if err := s.archiver.Archive(ctx, *file.StoragePath, s.options.StorageClass); err != nil {
+		return err
+	}
+	// 对象已归档但记录失败时，下次扫描会再次转换同一对象，操作幂等
+	return s.fileRepo.MarkArchived(ctx, file.ID, s.options.StorageClass, now)
+}
+
+// RequestRestore 对归档文件发起恢复，days为0时使用默认保留天数
+//
+// 恢复中重复发起直接返回当前状态；已恢复的文件再次发起会延长保留期
+func (s *archiveService) RequestRestore(ctx context.Context, userID, fileID uint, days int) (*ArchiveStatus, error) {
+	if days == 0 {
+		days = s.options.RestoreDays
+	}
+	if days < 0 || days > s.options.MaxRestoreDays {
+		return nil, pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "恢复保留天数必须在1到%d之间", s.options.MaxRestoreDays)
+	}
+
+	file, err := s.getOwnedFile(ctx, userID, fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	switch file.ArchiveState(now) {
+	case models.ArchiveStateAvailable:
+		return nil, pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "文件未归档，无需恢复")
+	case models.ArchiveStateRestoring:
+		return s.newArchiveStatus(file, now), nil
+	}
+
+	if err := s.archiver.Restore(ctx, *file.StoragePath, days, s.options.RestoreTier); err != nil {
+		return nil, fmt.Errorf("发起文件恢复失败: %w", err)
+	}
+	if err := s.fileRepo.UpdateRestoreState(ctx, file.ID, &now, file.RestoredUntil); err != nil {
+		return nil, fmt.Errorf("保存恢复状态失败: %w", err)
+	}
+	file.RestoreRequestedAt = &now
+
+	s.logger.Info("Archive restore requested",
+		zap.Uint("user_id", userID),
+		zap.Uint("file_id", file.ID),
+		zap.Int("days", days),
+		zap.String("tier", s.options.RestoreTier))
+	return s.newArchiveStatus(file, now), nil
+}
+
+// GetArchiveStatus 查询文件归档状态，恢复中时向存储服务同步最新进度
+func (s *archiveService) GetArchiveStatus(ctx context.Context, userID, fileID uint) (*ArchiveStatus, error) {
+	file, err := s.getOwnedFile(ctx, userID, fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	if file.ArchiveState(now) == models.ArchiveStateRestoring {
+		if err := s.syncRestoreState(ctx, file); err != nil {
+			// 同步失败时返回本地记录的状态，客户端继续轮询
+			s.logger.Warn("Failed to sync archive restore state",
+				zap.Uint("file_id", file.ID),
+				zap.Error(err))
+		}
+	}
+	return s.newArchiveStatus(file, now), nil
+}
+
+// syncRestoreState 从存储服务读取恢复进度，恢复完成时保存截止时间
+func (s *archiveService) syncRestoreState(ctx context.Context, file *models.File) error {
+	info, err := s.archiver.HeadObject(ctx, *file.StoragePath)
+	if err != nil {
+		return err
+	}
+	if info.RestoreOngoing || info.RestoredUntil == nil {
+		return nil
+	}
+	if err := s.fileRepo.UpdateRestoreState(ctx, file.ID, file.RestoreRequestedAt, info.RestoredUntil); err != nil {
+		return err
+	}
+	file.RestoredUntil = info.RestoredUntil
+	return nil
+}
+
+// getOwnedFile 获取用户自己的对象存储文件
+func (s *archiveService) getOwnedFile(ctx context.Context, userID, fileID uint) (*models.File, error) {
+	if userID == 0 || fileID == 0 {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "用户ID和文件ID不能为空")
+	}
+
+	file, err := s.fileRepo.GetByID(ctx, fileID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "文件不存在")
+		}
+		return nil, fmt.Errorf("获取文件失败: %w", err)
+	}
+	if file.UserID != userID {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, "无权访问该文件")
+	}
+	if file.IsFolder || file.StoragePath == nil {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "该文件不支持归档")
+	}
+	return file, nil
+}
+
+// newArchiveStatus 根据文件记录生成归档状态
+func (s *archiveService) newArchiveStatus(file *models.File, now time.Time) *ArchiveStatus {
+	status := &ArchiveStatus{
+		FileID:             file.ID,
+		State:              file.ArchiveState(now),
+		StorageClass:       file.StorageClass,
+		ArchivedAt:         file.ArchivedAt,
+		RestoreRequestedAt: file.RestoreRequestedAt,
+		RestoredUntil:      file.RestoredUntil,
+	}
+	if status.State == models.ArchiveStateRestoring {
+		status.PollInterval = int(RestorePollInterval / time.Second)
+	}
+	return status
+}
@@ -0,0 +1,197 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/storage"
+	"cloudpan/internal/repository/models"
+)
+
+// MockObjectArchiver 模拟对象归档
+type MockObjectArchiver struct {
+	mock.Mock
+}
+
+func (m *MockObjectArchiver) Archive(ctx context.Context, key, storageClass string) error {
+	return m.Called(ctx, key, storageClass).Error(0)
+}
+
+func (m *MockObjectArchiver) Restore(ctx context.Context, key string, days int, tier string) error {
+	return m.Called(ctx, key, days, tier).Error(0)
+}
+
+func (m *MockObjectArchiver) HeadObject(ctx context.Context, key string) (*storage.ObjectInfo, error) {
+	args := m.Called(ctx, key)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*storage.ObjectInfo), args.Error(1)
+}
+
+var archiveTestNow = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestArchiveService(repo *MockFileRepository, archiver *MockObjectArchiver) *archiveService {
+	svc := NewArchiveService(repo, archiver, ArchiveOptions{
+		TransitionAfter: 90 * 24 * time.Hour,
+		MinSize:         1024,
+		BatchSize:       10,
+	}, zap.NewNop()).(*archiveService)
+	svc.now = func() time.Time { return archiveTestNow }
+	return svc
+}
+
+func newStoredFile(id, userID uint) *models.File {
+	f := newTestFile(id, userID, nil, "backup.tar", false)
+	f.StoragePath = strPtr("files/backup.tar")
+	f.StorageClass = storage.StorageClassStandard
+	f.Status = "active"
+	return f
+}
+
+func archivedAt(hoursAgo int) *time.Time {
+	t := archiveTestNow.Add(-time.Duration(hoursAgo) * time.Hour)
+	return &t
+}
+
+func TestArchiveService_TransitionColdFiles(t *testing.T) {
+	ctx := context.Background()
+	repo := new(MockFileRepository)
+	archiver := new(MockObjectArchiver)
+	svc := newTestArchiveService(repo, archiver)
+
+	first, second := newStoredFile(1, 7), newStoredFile(2, 7)
+	second.StoragePath = strPtr("files/broken")
+	repo.On("ListArchiveCandidates", ctx, archiveTestNow.Add(-90*24*time.Hour), int64(1024), int64(storage.MaxCopyObjectSize), 10).
+		Return([]*models.File{first, second}, nil)
+	archiver.On("Archive", ctx, "files/backup.tar", storage.StorageClassGlacier).Return(nil)
+	archiver.On("Archive", ctx, "files/broken", storage.StorageClassGlacier).Return(errors.New("access denied"))
+	repo.On("MarkArchived", ctx, uint(1), storage.StorageClassGlacier, archiveTestNow).Return(nil)
+
+	report, err := svc.TransitionColdFiles(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &TransitionReport{Scanned: 2, Archived: 1, Failed: 1}, report)
+	repo.AssertNotCalled(t, "MarkArchived", ctx, uint(2), mock.Anything, mock.Anything)
+}
+
+func TestArchiveService_RequestRestore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("archived file", func(t *testing.T) {
+		repo := new(MockFileRepository)
+		archiver := new(MockObjectArchiver)
+		svc := newTestArchiveService(repo, archiver)
+		file := newStoredFile(1, 7)
+		file.ArchivedAt = archivedAt(1000)
+		repo.On("GetByID", ctx, uint(1)).Return(file, nil)
+		archiver.On("Restore", ctx, "files/backup.tar", DefaultRestoreDays, storage.RestoreTierStandard).Return(nil)
+		repo.On("UpdateRestoreState", ctx, uint(1), &archiveTestNow, (*time.Time)(nil)).Return(nil)
+
+		status, err := svc.RequestRestore(ctx, 7, 1, 0)
+		require.NoError(t, err)
+		assert.Equal(t, models.ArchiveStateRestoring, status.State)
+		assert.Equal(t, int(RestorePollInterval/time.Second), status.PollInterval)
+		archiver.AssertExpectations(t)
+	})
+
+	t.Run("already restoring", func(t *testing.T) {
+		repo := new(MockFileRepository)
+		archiver := new(MockObjectArchiver)
+		svc := newTestArchiveService(repo, archiver)
+		file := newStoredFile(1, 7)
+		file.ArchivedAt = archivedAt(1000)
+		file.RestoreRequestedAt = archivedAt(1)
+		repo.On("GetByID", ctx, uint(1)).Return(file, nil)
+
+		status, err := svc.RequestRestore(ctx, 7, 1, 3)
+		require.NoError(t, err)
+		assert.Equal(t, models.ArchiveStateRestoring, status.State)
+		archiver.AssertNotCalled(t, "Restore", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("not archived", func(t *testing.T) {
+		repo := new(MockFileRepository)
+		svc := newTestArchiveService(repo, new(MockObjectArchiver))
+		repo.On("GetByID", ctx, uint(1)).Return(newStoredFile(1, 7), nil)
+
+		_, err := svc.RequestRestore(ctx, 7, 1, 0)
+		assert.ErrorIs(t, err, pkgErrors.ErrOperationNotAllowed)
+	})
+
+	t.Run("invalid days", func(t *testing.T) {
+		svc := newTestArchiveService(new(MockFileRepository), new(MockObjectArchiver))
+
+		_, err := svc.RequestRestore(ctx, 7, 1, 365)
+		assert.True(t, pkgErrors.IsValidationError(err))
+	})
+
+	t.Run("other user", func(t *testing.T) {
+		repo := new(MockFileRepository)
+		svc := newTestArchiveService(repo, new(MockObjectArchiver))
+		repo.On("GetByID", ctx, uint(1)).Return(newStoredFile(1, 8), nil)
+
+		_, err := svc.RequestRestore(ctx, 7, 1, 0)
+		assert.True(t, pkgErrors.IsPermissionError(err))
+	})
+}
+
+func TestArchiveService_GetArchiveStatus(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("restore completed", func(t *testing.T) {
+		repo := new(MockFileRepository)
+		archiver := new(MockObjectArchiver)
+		svc := newTestArchiveService(repo, archiver)
+		file := newStoredFile(1, 7)
+		file.ArchivedAt = archivedAt(1000)
+		file.RestoreRequestedAt = archivedAt(5)
+		until := archiveTestNow.Add(7 * 24 * time.Hour)
+		repo.On("GetByID", ctx, uint(1)).Return(file, nil)
+		archiver.On("HeadObject", ctx, "files/backup.tar").Return(&storage.ObjectInfo{RestoredUntil: &until}, nil)
+		repo.On("UpdateRestoreState", ctx, uint(1), file.RestoreRequestedAt, &until).Return(nil)
+
+		status, err := svc.GetArchiveStatus(ctx, 7, 1)
+		require.NoError(t, err)
+		assert.Equal(t, models.ArchiveStateRestored, status.State)
+		assert.Equal(t, &until, status.RestoredUntil)
+		assert.Zero(t, status.PollInterval)
+	})
+
+	t.Run("restore ongoing", func(t *testing.T) {
+		repo := new(MockFileRepository)
+		archiver := new(MockObjectArchiver)
+		svc := newTestArchiveService(repo, archiver)
+		file := newStoredFile(1, 7)
+		file.ArchivedAt = archivedAt(1000)
+		file.RestoreRequestedAt = archivedAt(1)
+		repo.On("GetByID", ctx, uint(1)).Return(file, nil)
+		archiver.On("HeadObject", ctx, "files/backup.tar").Return(&storage.ObjectInfo{RestoreOngoing: true}, nil)
+
+		status, err := svc.GetArchiveStatus(ctx, 7, 1)
+		require.NoError(t, err)
+		assert.Equal(t, models.ArchiveStateRestoring, status.State)
+		repo.AssertNotCalled(t, "UpdateRestoreState", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("archived without restore does not query storage", func(t *testing.T) {
+		repo := new(MockFileRepository)
+		archiver := new(MockObjectArchiver)
+		svc := newTestArchiveService(repo, archiver)
+		file := newStoredFile(1, 7)
+		file.ArchivedAt = archivedAt(1000)
+		repo.On("GetByID", ctx, uint(1)).Return(file, nil)
+
+		status, err := svc.GetArchiveStatus(ctx, 7, 1)
+		require.NoError(t, err)
+		assert.Equal(t, models.ArchiveStateArchived, status.State)
+		archiver.AssertNotCalled(t, "HeadObject", mock.Anything, mock.Anything)
+	})
+}
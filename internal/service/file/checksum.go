@@ -0,0 +1,55 @@
+package file
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FolderChecksumAlgorithm 文件夹组合校验和使用的算法
+const FolderChecksumAlgorithm = "sha256"
+
+// ChecksumEntry 参与组合校验和计算的子项
+type ChecksumEntry struct {
+	Name     string // 子项名称
+	IsFolder bool   // 是否为文件夹
+	Size     int64  // 文件大小(文件夹为0)
+	Hash     string // 文件哈希或子文件夹组合校验和
+}
+
+// ComputeCompositeChecksum 计算确定性的组合校验和
+//
+// 子项按名称排序后逐行序列化为 "类型|名称|大小|哈希"，
+// 再对整体做SHA256，保证同样的子树在任何实例上得到相同结果
+func ComputeCompositeChecksum(entries []ChecksumEntry) string {
+	sorted := make([]ChecksumEntry, len(entries))
+	copy(sorted, entries)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Name != sorted[j].Name {
+			return sorted[i].Name < sorted[j].Name
+		}
+		// 同名时文件夹排在文件前面
+		return sorted[i].IsFolder && !sorted[j].IsFolder
+	})
+
+	var builder strings.Builder
+	for _, entry := range sorted {
+		kind := "f"
+		if entry.IsFolder {
+			kind = "d"
+		}
+		builder.WriteString(kind)
+		builder.WriteByte('|')
+		builder.WriteString(entry.Name)
+		builder.WriteByte('|')
+		builder.WriteString(strconv.FormatInt(entry.Size, 10))
+		builder.WriteByte('|')
+		builder.WriteString(strings.ToLower(entry.Hash))
+		builder.WriteByte('\n')
+	}
+
+	sum := sha256.Sum256([]byte(builder.String()))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,101 @@
+package file
+
+import (
+	"crypto/md5" // #nosec G501 - 仅用于分片完整性校验，非安全用途
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"strings"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+)
+
+// crc32cTable Castagnoli多项式表，现代CPU上有硬件加速
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+// supportedChunkHashAlgorithms 服务端支持的分片校验算法，按优先级排列
+var supportedChunkHashAlgorithms = []string{
+	models.ChunkHashAlgorithmCRC32C,
+	models.ChunkHashAlgorithmMD5,
+}
+
+// SupportedChunkHashAlgorithms 返回服务端支持的分片校验算法(按优先级排列)
+func SupportedChunkHashAlgorithms() []string {
+	algorithms := make([]string, len(supportedChunkHashAlgorithms))
+	copy(algorithms, supportedChunkHashAlgorithms)
+	return algorithms
+}
+
+// NegotiateChunkHashAlgorithm 根据客户端声明的算法列表协商分片校验算法
+//
+// 客户端未声明时沿用旧协议的MD5；否则按服务端优先级选择双方都支持的算法，
+// 没有交集时返回验证错误
+func NegotiateChunkHashAlgorithm(requested []string) (string, error) {
+	if len(requested) == 0 {
+		return models.ChunkHashAlgorithmMD5, nil
+	}
+
+	accepted := make(map[string]bool, len(requested))
+	for _, algorithm := range requested {
+		accepted[normalizeChunkHashAlgorithm(algorithm)] = true
+	}
+
+	for _, algorithm := range supportedChunkHashAlgorithms {
+		if accepted[algorithm] {
+			return algorithm, nil
+		}
+	}
+
+	return "", pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput,
+		"不支持的分片校验算法: %s", strings.Join(requested, ","))
+}
+
+// NewChunkHasher 创建指定算法的流式哈希器
+func NewChunkHasher(algorithm string) (hash.Hash, error) {
+	switch normalizeChunkHashAlgorithm(algorithm) {
+	case models.ChunkHashAlgorithmCRC32C:
+		return crc32.New(crc32cTable), nil
+	case models.ChunkHashAlgorithmMD5:
+		return md5.New(), nil // #nosec G401 - 仅用于分片完整性校验
+	default:
+		return nil, pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "不支持的分片校验算法: %s", algorithm)
+	}
+}
+
+// VerifyChunk 以流式方式读取分片并校验哈希
+//
+// 数据同时写入dst(可为nil)，避免为校验而将整个分片读入内存。
+// 返回写入的字节数；哈希不一致时返回包装了ErrFileCorrupted的错误
+func VerifyChunk(dst io.Writer, src io.Reader, algorithm, expected string) (int64, error) {
+	hasher, err := NewChunkHasher(algorithm)
+	if err != nil {
+		return 0, err
+	}
+
+	writer := io.Writer(hasher)
+	if dst != nil {
+		writer = io.MultiWriter(dst, hasher)
+	}
+
+	written, err := utils.PooledCopy(writer, src)
+	if err != nil {
+		return written, fmt.Errorf("读取分片数据失败: %w", err)
+	}
+
+	actual := hex.EncodeToString(hasher.Sum(nil))
+	if !strings.EqualFold(actual, strings.TrimSpace(expected)) {
+		return written, pkgErrors.WrapErrorf(pkgErrors.ErrFileCorrupted,
+			"分片校验失败(%s): 期望 %s，实际 %s", algorithm, expected, actual)
+	}
+
+	return written, nil
+}
+
+// normalizeChunkHashAlgorithm 规范化算法名称(忽略大小写和连字符，如 CRC-32C)
+func normalizeChunkHashAlgorithm(algorithm string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(algorithm)), "-", "")
+}
@@ -0,0 +1,90 @@
+package file
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/repository/models"
+)
+
+func TestNegotiateChunkHashAlgorithm(t *testing.T) {
+	tests := []struct {
+		name      string
+		requested []string
+		expected  string
+		wantErr   bool
+	}{
+		{"legacy client defaults to md5", nil, models.ChunkHashAlgorithmMD5, false},
+		{"prefers crc32c", []string{"md5", "crc32c"}, models.ChunkHashAlgorithmCRC32C, false},
+		{"accepts aliases", []string{"CRC-32C"}, models.ChunkHashAlgorithmCRC32C, false},
+		{"md5 only", []string{"MD5"}, models.ChunkHashAlgorithmMD5, false},
+		{"unsupported", []string{"sha512"}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			algorithm, err := NegotiateChunkHashAlgorithm(tt.requested)
+			if tt.wantErr {
+				assert.True(t, pkgErrors.IsValidationError(err))
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, algorithm)
+		})
+	}
+}
+
+func TestVerifyChunk(t *testing.T) {
+	data := "hello world"
+
+	t.Run("crc32c", func(t *testing.T) {
+		var dst bytes.Buffer
+		n, err := VerifyChunk(&dst, strings.NewReader(data), models.ChunkHashAlgorithmCRC32C, "c99465aa")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(len(data)), n)
+		assert.Equal(t, data, dst.String())
+	})
+
+	t.Run("md5 is case insensitive", func(t *testing.T) {
+		_, err := VerifyChunk(nil, strings.NewReader(data), models.ChunkHashAlgorithmMD5, "5EB63BBBE01EEED093CB22BB8F5ACDC3")
+		assert.NoError(t, err)
+	})
+
+	t.Run("mismatch", func(t *testing.T) {
+		_, err := VerifyChunk(nil, strings.NewReader(data), models.ChunkHashAlgorithmCRC32C, "00000000")
+		assert.ErrorIs(t, err, pkgErrors.ErrFileCorrupted)
+	})
+
+	t.Run("unsupported algorithm", func(t *testing.T) {
+		_, err := VerifyChunk(nil, strings.NewReader(data), "sha1", "x")
+		assert.True(t, pkgErrors.IsValidationError(err))
+	})
+}
+
+func BenchmarkVerifyChunk(b *testing.B) {
+	data := bytes.Repeat([]byte("cloudpan"), 512*1024) // 4MB
+	for _, algorithm := range SupportedChunkHashAlgorithms() {
+		b.Run(algorithm, func(b *testing.B) {
+			hasher, err := NewChunkHasher(algorithm)
+			if err != nil {
+				b.Fatal(err)
+			}
+			hasher.Write(data)
+			expected := hex.EncodeToString(hasher.Sum(nil))
+
+			b.SetBytes(int64(len(data)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := VerifyChunk(io.Discard, bytes.NewReader(data), algorithm, expected); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,276 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"  // #nosec G501 - 仅用于文件完整性校验，非安全用途
+	"crypto/sha1" // #nosec G505 - 仅用于文件完整性校验，非安全用途
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/storage"
+	"cloudpan/internal/pkg/utils"
+)
+
+// DefaultMergeParallelism 默认并行预读的分片数量
+const DefaultMergeParallelism = 4
+
+// MergeChunk 待合并的分片
+type MergeChunk struct {
+	Index       int    // 分片索引
+	Size        int64  // 分片大小
+	StoragePath string // 分片存储路径
+}
+
+// MergeRequest 分片合并请求
+type MergeRequest struct {
+	DestPath     string       // 合并后文件的存储路径
+	Chunks       []MergeChunk // 全部分片(无需有序)
+	ExpectedSize int64        // 期望的文件总大小
+	ExpectedHash string       // 期望的文件哈希(为空则不校验)
+	HashType     string       // 文件哈希算法(md5/sha1/sha256)
+}
+
+// MergeResult 分片合并结果
+type MergeResult struct {
+	Size     int64  // 实际写入字节数
+	Hash     string // 实际文件哈希
+	Composed bool   // 是否由存储后端服务端拼接完成
+}
+
+// ChunkMerger 分片合并器
+//
+// 合并时直接写入最终存储位置，不产生中间临时文件：
+// 1. 支持服务端拼接的后端(S3/OSS)使用分片复制，完成后流式回读校验哈希
+// 2. 其他后端并行预读后续分片，按顺序写入目标并同时计算整体哈希
+//
+// 并行预读最多占用 parallelism 个分片大小的内存
+type ChunkMerger struct {
+	store       storage.Storage
+	parallelism int
+	logger      *zap.Logger
+}
+
+// NewChunkMerger 创建分片合并器
+func NewChunkMerger(store storage.Storage, parallelism int, logger *zap.Logger) *ChunkMerger {
+	if parallelism <= 0 {
+		parallelism = DefaultMergeParallelism
+	}
+	return &ChunkMerger{
+		store:       store,
+		parallelism: parallelism,
+		logger:      logger,
+	}
+}
+
+// Merge 合并分片到目标路径
+//
+// 校验失败时会删除已写入的目标对象，分片本身保持不变以便重试
+func (m *ChunkMerger) Merge(ctx context.Context, req MergeRequest) (*MergeResult, error) {
+	chunks, err := sortMergeChunks(req.Chunks)
+	if err != nil {
+		return nil, err
+	}
+
+	hasher, err := NewFileHasher(req.HashType)
+	if err != nil {
+		return nil, err
+	}
+
+	var result *MergeResult
+	if composer, ok := storage.Unwrap(m.store).(storage.Composer); ok {
+		result, err = m.compose(ctx, composer, req.DestPath, chunks, hasher)
+	} else {
+		result, err = m.stream(ctx, req.DestPath, chunks, hasher)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if verifyErr := verifyMergeResult(req, result); verifyErr != nil {
+		if delErr := m.store.Delete(ctx, req.DestPath); delErr != nil {
+			m.logger.Warn("Failed to remove corrupted merge result",
+				zap.String("path", req.DestPath),
+				zap.Error(delErr))
+		}
+		return nil, verifyErr
+	}
+
+	return result, nil
+}
+
+// compose 使用存储后端的服务端拼接，再流式回读计算哈希
+func (m *ChunkMerger) compose(ctx context.Context, composer storage.Composer, dest string, chunks []MergeChunk, hasher hash.Hash) (*MergeResult, error) {
+	sources := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		sources[i] = chunk.StoragePath
+	}
+
+	if err := composer.Compose(ctx, dest, sources); err != nil {
+		return nil, fmt.Errorf("服务端拼接分片失败: %w", err)
+	}
+
+	reader, err := m.store.Open(ctx, dest)
+	if err != nil {
+		return nil, fmt.Errorf("读取合并结果失败: %w", err)
+	}
+	defer reader.Close()
+
+	size, err := io.Copy(hasher, reader)
+	if err != nil {
+		return nil, fmt.Errorf("校验合并结果失败: %w", err)
+	}
+
+	return &MergeResult{
+		Size:     size,
+		Hash:     hex.EncodeToString(hasher.Sum(nil)),
+		Composed: true,
+	}, nil
+}
+
+// prefetchedChunk 预读完成的分片数据
+type prefetchedChunk struct {
+	data *bytes.Buffer
+	err  error
+}
+
+// stream 并行预读分片，按顺序写入目标并计算哈希
+func (m *ChunkMerger) stream(ctx context.Context, dest string, chunks []MergeChunk, hasher hash.Hash) (*MergeResult, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	writer, err := m.store.Create(ctx, dest)
+	if err != nil {
+		return nil, fmt.Errorf("创建目标文件失败: %w", err)
+	}
+
+	// 每个分片一个结果通道，信号量限制同时在内存中的分片数量
+	results := make([]chan prefetchedChunk, len(chunks))
+	for i := range results {
+		results[i] = make(chan prefetchedChunk, 1)
+	}
+	slots := make(chan struct{}, m.parallelism)
+
+	go func() {
+		for i, chunk := range chunks {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				results[i] <- prefetchedChunk{err: ctx.Err()}
+				continue
+			}
+			go func(chunk MergeChunk, out chan<- prefetchedChunk) {
+				data, err := m.readChunk(ctx, chunk)
+				out <- prefetchedChunk{data: data, err: err}
+			}(chunk, results[i])
+		}
+	}()
+
+	target := io.MultiWriter(writer, hasher)
+	var written int64
+	for i, chunk := range chunks {
+		fetched := <-results[i]
+		if fetched.err != nil {
+			_ = writer.Abort()
+			return nil, fmt.Errorf("读取分片 %d 失败: %w", chunk.Index, fetched.err)
+		}
+
+		n, err := fetched.data.WriteTo(target)
+		written += n
+		utils.PutBytesBuffer(fetched.data)
+		<-slots
+		if err != nil {
+			_ = writer.Abort()
+			return nil, fmt.Errorf("写入分片 %d 失败: %w", chunk.Index, err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		_ = writer.Abort()
+		return nil, fmt.Errorf("关闭目标文件失败: %w", err)
+	}
+
+	return &MergeResult{
+		Size: written,
+		Hash: hex.EncodeToString(hasher.Sum(nil)),
+	}, nil
+}
+
+// readChunk 读取单个分片的全部数据
+func (m *ChunkMerger) readChunk(ctx context.Context, chunk MergeChunk) (*bytes.Buffer, error) {
+	reader, err := m.store.Open(ctx, chunk.StoragePath)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	// 分片缓冲区在写入目标后归还到池中，合并大量文件时避免反复分配
+	buf := utils.GetBytesBuffer()
+	buf.Grow(int(chunk.Size))
+	n, err := buf.ReadFrom(reader)
+	if err != nil {
+		utils.PutBytesBuffer(buf)
+		return nil, err
+	}
+	if chunk.Size > 0 && n != chunk.Size {
+		utils.PutBytesBuffer(buf)
+		return nil, pkgErrors.WrapErrorf(pkgErrors.ErrFileCorrupted,
+			"分片大小不一致: 期望 %d，实际 %d", chunk.Size, n)
+	}
+	return buf, nil
+}
+
+// NewFileHasher 创建整体文件哈希器，未指定算法时默认MD5
+func NewFileHasher(hashType string) (hash.Hash, error) {
+	switch strings.ToLower(hashType) {
+	case "", "md5":
+		return md5.New(), nil // #nosec G401 - 仅用于文件完整性校验
+	case "sha1":
+		return sha1.New(), nil // #nosec G401 - 仅用于文件完整性校验
+	case "sha256":
+		return sha256.New(), nil
+	default:
+		return nil, pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "不支持的文件哈希算法: %s", hashType)
+	}
+}
+
+// sortMergeChunks 按索引排序并检查分片是否连续完整
+func sortMergeChunks(chunks []MergeChunk) ([]MergeChunk, error) {
+	if len(chunks) == 0 {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "没有可合并的分片")
+	}
+
+	sorted := make([]MergeChunk, len(chunks))
+	copy(sorted, chunks)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Index < sorted[j].Index
+	})
+
+	for i, chunk := range sorted {
+		if chunk.Index != i {
+			return nil, pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "分片不完整: 缺少分片 %d", i)
+		}
+	}
+	return sorted, nil
+}
+
+// verifyMergeResult 校验合并后的大小和哈希
+func verifyMergeResult(req MergeRequest, result *MergeResult) error {
+	if req.ExpectedSize > 0 && result.Size != req.ExpectedSize {
+		return pkgErrors.WrapErrorf(pkgErrors.ErrFileCorrupted,
+			"合并后文件大小不一致: 期望 %d，实际 %d", req.ExpectedSize, result.Size)
+	}
+	if req.ExpectedHash != "" && !strings.EqualFold(req.ExpectedHash, result.Hash) {
+		return pkgErrors.WrapErrorf(pkgErrors.ErrFileCorrupted,
+			"合并后文件哈希不一致: 期望 %s，实际 %s", req.ExpectedHash, result.Hash)
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package file
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/storage"
+)
+
+// composingStorage 模拟支持服务端拼接的存储后端
+type composingStorage struct {
+	*storage.LocalStorage
+	composed bool
+}
+
+func (s *composingStorage) Compose(ctx context.Context, dst string, sources []string) error {
+	s.composed = true
+	readers := make([]io.Reader, 0, len(sources))
+	for _, source := range sources {
+		reader, err := s.Open(ctx, source)
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+		readers = append(readers, reader)
+	}
+	return s.Put(ctx, dst, io.MultiReader(readers...), -1)
+}
+
+// prepareChunks 写入测试分片并返回合并请求
+func prepareChunks(t *testing.T, store storage.Storage, parts []string) MergeRequest {
+	t.Helper()
+	ctx := context.Background()
+
+	req := MergeRequest{DestPath: "files/merged.bin", HashType: "sha256"}
+	full := strings.Join(parts, "")
+	// 倒序提供分片，验证合并器会按索引排序
+	for i := len(parts) - 1; i >= 0; i-- {
+		path := fmt.Sprintf("chunks/upload-1/%d", i)
+		require.NoError(t, store.Put(ctx, path, strings.NewReader(parts[i]), int64(len(parts[i]))))
+		req.Chunks = append(req.Chunks, MergeChunk{Index: i, Size: int64(len(parts[i])), StoragePath: path})
+	}
+
+	sum := sha256.Sum256([]byte(full))
+	req.ExpectedHash = hex.EncodeToString(sum[:])
+	req.ExpectedSize = int64(len(full))
+	return req
+}
+
+func readAll(t *testing.T, store storage.Storage, path string) string {
+	t.Helper()
+	reader, err := store.Open(context.Background(), path)
+	require.NoError(t, err)
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestChunkMerger_Stream(t *testing.T) {
+	store, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+
+	parts := []string{"alpha-", "bravo-", "charlie-", "delta-", "echo"}
+	req := prepareChunks(t, store, parts)
+
+	merger := NewChunkMerger(store, 2, zap.NewNop())
+	result, err := merger.Merge(context.Background(), req)
+	require.NoError(t, err)
+
+	assert.False(t, result.Composed)
+	assert.Equal(t, req.ExpectedSize, result.Size)
+	assert.Equal(t, req.ExpectedHash, result.Hash)
+	assert.Equal(t, strings.Join(parts, ""), readAll(t, store, req.DestPath))
+}
+
+func TestChunkMerger_Compose(t *testing.T) {
+	local, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	store := &composingStorage{LocalStorage: local}
+
+	parts := []string{"one", "two", "three"}
+	req := prepareChunks(t, store, parts)
+
+	result, err := NewChunkMerger(store, 0, zap.NewNop()).Merge(context.Background(), req)
+	require.NoError(t, err)
+	assert.True(t, store.composed)
+	assert.True(t, result.Composed)
+	assert.Equal(t, req.ExpectedHash, result.Hash)
+}
+
+func TestChunkMerger_HashMismatchRemovesDestination(t *testing.T) {
+	store, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+
+	req := prepareChunks(t, store, []string{"a", "b"})
+	req.ExpectedHash = strings.Repeat("0", 64)
+
+	_, err = NewChunkMerger(store, 2, zap.NewNop()).Merge(context.Background(), req)
+	assert.ErrorIs(t, err, pkgErrors.ErrFileCorrupted)
+
+	exists, err := store.Exists(context.Background(), req.DestPath)
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestChunkMerger_MissingChunk(t *testing.T) {
+	store, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+
+	req := prepareChunks(t, store, []string{"a", "b", "c"})
+	// 去掉索引为1的分片
+	req.Chunks = []MergeChunk{req.Chunks[0], req.Chunks[2]}
+
+	_, err = NewChunkMerger(store, 2, zap.NewNop()).Merge(context.Background(), req)
+	assert.True(t, pkgErrors.IsValidationError(err))
+}
+
+func BenchmarkChunkMerger_Stream(b *testing.B) {
+	store, err := storage.NewLocalStorage(b.TempDir())
+	if err != nil {
+		b.Fatal(err)
+	}
+	ctx := context.Background()
+
+	chunk := strings.Repeat("x", 1024*1024)
+	req := MergeRequest{DestPath: "files/merged.bin", HashType: "sha256"}
+	for i := 0; i < 8; i++ {
+		path := fmt.Sprintf("chunks/bench/%d", i)
+		if err := store.Put(ctx, path, strings.NewReader(chunk), int64(len(chunk))); err != nil {
+			b.Fatal(err)
+		}
+		req.Chunks = append(req.Chunks, MergeChunk{Index: i, Size: int64(len(chunk)), StoragePath: path})
+		req.ExpectedSize += int64(len(chunk))
+	}
+
+	merger := NewChunkMerger(store, 4, zap.NewNop())
+	b.SetBytes(req.ExpectedSize)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := merger.Merge(ctx, req); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
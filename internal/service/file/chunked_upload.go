@@ -0,0 +1,943 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/clock"
+	"cloudpan/internal/pkg/config"
+	basemodels "cloudpan/internal/pkg/database/models"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/idgen"
+	"cloudpan/internal/pkg/storage"
+	"cloudpan/internal/pkg/utils"
+	filerepo "cloudpan/internal/repository/file"
+	"cloudpan/internal/repository/models"
+)
+
+// 分片上传默认值
+const (
+	DefaultChunkSize            = 5 * 1024 * 1024         // 5MB
+	DefaultChunkedUploadMaxSize = 50 * 1024 * 1024 * 1024 // 50GB
+	DefaultChunkUploadTTL       = 24 * time.Hour
+	DefaultChunkCleanupBatch    = 500
+	MaxUploadChunks             = 10000
+)
+
+// 上传任务元数据键，保存在上传中文件记录的Metadata中
+const (
+	uploadMetaChunkSize     = "chunk_size"
+	uploadMetaTotalChunks   = "total_chunks"
+	uploadMetaHashAlgorithm = "chunk_hash_algorithm"
+	uploadMetaExpiresAt     = "upload_expires_at"
+	uploadMetaStartedAt     = "upload_started_at"
+	uploadMetaClientType    = "upload_client"
+)
+
+// ChunkedUploadService 分片上传服务接口
+//
+// 大文件按固定大小分片上传到应用服务器，支持断点续传：
+// 1. 申请：校验文件名、大小和目标文件夹的子项数，按声明大小预留存储空间，登记上传中的文件记录，协商分片校验算法
+// 2. 上传分片：流式写入存储并校验分片哈希，重传同一分片会覆盖
+// 3. 查询：返回已接收的分片索引，客户端中断后只需补传缺失分片
+// 4. 合并：全部分片到齐后合并为最终文件，校验整体大小和哈希，激活文件、提交预留并删除分片
+// 5. 取消：删除已上传的分片和上传中的文件记录，释放预留
+//
+// 上传中的文件记录作为占位文件显示在文件夹列表中，AttachProgress 为其填充上传进度，其他设备可以看到正在上传的文件。
+// 过期未合并的上传由 CleanupExpired 定期清理，删除分片和占位文件并释放预留。
+// 配置 TTLTuner 时有效期按客户端类型观察到的完成耗时调整，上传过程中收到分片时滑动延长
+//
+// 使用示例：
+//
+//	service := NewChunkedUploadService(fileRepo, chunkRepo, quotaService, store, progressHub, folderLimiter, ChunkedUploadOptions{}, nil, nil, logger)
+//	session, err := service.Initiate(ctx, userID, &ChunkedUploadRequest{Name: "a.iso", Size: size, Hash: md5})
+//	_, err = service.UploadChunk(ctx, userID, session.UploadID, &ChunkUpload{Index: 0, Hash: crc, Size: n, Data: body})
+//	file, err := service.Merge(ctx, userID, session.UploadID)
+type ChunkedUploadService interface {
+	Initiate(ctx context.Context, userID uint, req *ChunkedUploadRequest) (*ChunkedUploadSession, error)
+	UploadChunk(ctx context.Context, userID uint, uploadID string, chunk *ChunkUpload) (*ChunkedUploadSession, error)
+	GetSession(ctx context.Context, userID uint, uploadID string) (*ChunkedUploadSession, error)
+	Merge(ctx context.Context, userID uint, uploadID string) (*models.File, error)
+	Abort(ctx context.Context, userID uint, uploadID string) error
+	AttachProgress(ctx context.Context, files []*models.File) error
+	CleanupExpired(ctx context.Context) (int, error)
+}
+
+// ProgressPublisher 上传进度发布，由 ProgressHub 实现
+type ProgressPublisher interface {
+	Publish(progress UploadProgress)
+}
+
+// ChunkedUploadOptions 分片上传选项
+type ChunkedUploadOptions struct {
+	ChunkSize        int64         // 分片大小(最后一个分片可以更小)
+	MaxSize          int64         // 允许上传的最大文件大小
+	TTL              time.Duration // 上传任务有效期，过期后分片被清理
+	KeyPrefix        string        // 合并后文件的存储路径前缀
+	MergeParallelism int           // 合并时并行预读的分片数
+	CleanupBatch     int           // 每次清理最多处理的过期分片数
+
+	// TTLTuner 按客户端调整有效期、滑动延长进行中的上传并统计完成和放弃情况，为nil时使用固定的 TTL
+	TTLTuner *UploadTTLTuner
+	// Checksums 登记占位文件和合并完成后使目标文件夹父链上的校验和失效，为nil时跳过
+	Checksums ChecksumInvalidator
+}
+
+// ChunkedUploadOptionsFromConfig 从上传配置生成选项，TTLTuner 和 Checksums 由调用方设置为共享的实例
+func ChunkedUploadOptionsFromConfig(cfg config.UploadConfig) ChunkedUploadOptions {
+	return ChunkedUploadOptions{
+		ChunkSize:        cfg.ChunkSize,
+		TTL:              cfg.TTL.Default,
+		MergeParallelism: cfg.MergeParallelism,
+	}
+}
+
+// ChunkedUploadRequest 分片上传申请
+type ChunkedUploadRequest struct {
+	Name                string   `json:"name" binding:"required"` // 文件名
+	Size                int64    `json:"size" binding:"required"` // 文件大小(字节)
+	Hash                string   `json:"hash" binding:"required"` // 文件整体哈希(十六进制)，合并后校验
+	HashType            string   `json:"hash_type"`               // 文件哈希算法(md5/sha1/sha256)，默认md5
+	MimeType            string   `json:"mime_type"`               // 内容类型
+	ParentID            *uint    `json:"parent_id"`               // 目标文件夹ID，为空表示根目录
+	ChunkHashAlgorithms []string `json:"chunk_hash_algorithms"`   // 客户端支持的分片校验算法，为空时使用md5
+	ClientType          string   `json:"client_type"`             // 客户端类型(web/desktop/mobile)，为空时按User-Agent推断
+}
+
+// ChunkUpload 单个分片的上传数据
+type ChunkUpload struct {
+	Index int       // 分片索引(从0开始)
+	Hash  string    // 分片哈希(十六进制)，算法为申请时协商的算法
+	Size  int64     // 声明的分片大小，必须与分片规划一致
+	Data  io.Reader // 分片内容
+}
+
+// ChunkedUploadSession 分片上传任务状态
+type ChunkedUploadSession struct {
+	UploadID           string    `json:"upload_id"`            // 上传任务ID(即文件UUID)
+	FileID             uint      `json:"file_id"`              // 文件ID
+	Name               string    `json:"name"`                 // 规范化后的文件名
+	Size               int64     `json:"size"`                 // 文件总大小
+	ChunkSize          int64     `json:"chunk_size"`           // 分片大小
+	TotalChunks        int       `json:"total_chunks"`         // 总分片数
+	ChunkHashAlgorithm string    `json:"chunk_hash_algorithm"` // 协商的分片校验算法
+	UploadedChunks     []int     `json:"uploaded_chunks"`      // 已接收的分片索引
+	ExpiresAt          time.Time `json:"expires_at"`           // 上传任务过期时间
+}
+
+// IsComplete 检查全部分片是否已接收
+func (s *ChunkedUploadSession) IsComplete() bool {
+	return len(s.UploadedChunks) == s.TotalChunks
+}
+
+// uploadPlan 保存在文件记录中的分片规划
+type uploadPlan struct {
+	chunkSize     int64
+	totalChunks   int
+	hashAlgorithm string
+	expiresAt     time.Time
+	startedAt     time.Time // 申请时间，早期的上传记录没有
+	clientType    string
+}
+
+// chunkedUploadService 分片上传服务实现
+type chunkedUploadService struct {
+	fileRepo  filerepo.FileRepository
+	chunkRepo filerepo.UploadChunkRepository
+	quota     QuotaAccountant
+	store     storage.Storage
+	merger    *ChunkMerger
+	progress  ProgressPublisher
+	folders   FolderLimiter
+	options   ChunkedUploadOptions
+	logger    *zap.Logger
+	clock     clock.Clock
+	ids       idgen.IDGenerator
+}
+
+// NewChunkedUploadService 创建分片上传服务，未配置的选项使用默认值
+//
+// progress 可为nil，为nil时不发布上传进度；folders 为nil时不限制目标文件夹的子项数；
+// clk为nil时使用系统时钟，ids为nil时使用全局配置的标识符生成器
+func NewChunkedUploadService(fileRepo filerepo.FileRepository, chunkRepo filerepo.UploadChunkRepository, quota QuotaAccountant, store storage.Storage, progress ProgressPublisher, folders FolderLimiter, options ChunkedUploadOptions, clk clock.Clock, ids idgen.IDGenerator, logger *zap.Logger) ChunkedUploadService {
+	if options.ChunkSize <= 0 {
+		options.ChunkSize = DefaultChunkSize
+	}
+	if options.MaxSize <= 0 {
+		options.MaxSize = DefaultChunkedUploadMaxSize
+	}
+	if options.TTL <= 0 {
+		options.TTL = DefaultChunkUploadTTL
+	}
+	options.KeyPrefix = strings.Trim(options.KeyPrefix, "/")
+	if options.KeyPrefix == "" {
+		options.KeyPrefix = "files"
+	}
+	if options.CleanupBatch <= 0 {
+		options.CleanupBatch = DefaultChunkCleanupBatch
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &chunkedUploadService{
+		fileRepo:  fileRepo,
+		chunkRepo: chunkRepo,
+		quota:     quota,
+		store:     store,
+		merger:    NewChunkMerger(store, options.MergeParallelism, logger),
+		progress:  progress,
+		folders:   folders,
+		options:   options,
+		logger:    logger,
+		clock:     clock.OrReal(clk),
+		ids:       idgen.OrDefault(ids),
+	}
+}
+
+// Initiate 申请分片上传并登记上传中的文件记录
+func (s *chunkedUploadService) Initiate(ctx context.Context, userID uint, req *ChunkedUploadRequest) (*ChunkedUploadSession, error) {
+	if userID == 0 || req == nil {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "用户ID和上传信息不能为空")
+	}
+
+	name, err := utils.NormalizeFileName(req.Name)
+	if err != nil {
+		return nil, err
+	}
+	if req.Size <= 0 || req.Size > s.options.MaxSize {
+		return nil, pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "文件大小必须在1到 %d 字节之间", s.options.MaxSize)
+	}
+	totalChunks := int((req.Size + s.options.ChunkSize - 1) / s.options.ChunkSize)
+	if totalChunks > MaxUploadChunks {
+		return nil, pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "分片数量不能超过 %d", MaxUploadChunks)
+	}
+
+	hashType := strings.ToLower(strings.TrimSpace(req.HashType))
+	if hashType == "" {
+		hashType = "md5"
+	}
+	if _, err := NewFileHasher(hashType); err != nil {
+		return nil, err
+	}
+	hash := strings.ToLower(strings.TrimSpace(req.Hash))
+	if hash == "" {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "文件哈希不能为空")
+	}
+
+	algorithm, err := NegotiateChunkHashAlgorithm(req.ChunkHashAlgorithms)
+	if err != nil {
+		return nil, err
+	}
+
+	parentPath, err := s.resolveParent(ctx, userID, req.ParentID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkFolderLimits(ctx, s.folders, userID, req.ParentID, parentPath, 0); err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now()
+	uploadID := s.ids.NewID()
+	key := path.Join(s.options.KeyPrefix, fmt.Sprintf("%d", userID), now.UTC().Format("2006/01"), uploadID)
+	contentType := strings.TrimSpace(req.MimeType)
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	clientType := NormalizeUploadClient(req.ClientType)
+	ttl := s.options.TTL
+	if s.options.TTLTuner != nil {
+		ttl = s.options.TTLTuner.TTL(clientType)
+	}
+	plan := uploadPlan{
+		chunkSize:     s.options.ChunkSize,
+		totalChunks:   totalChunks,
+		hashAlgorithm: algorithm,
+		expiresAt:     now.Add(ttl),
+		startedAt:     now,
+		clientType:    clientType,
+	}
+	if err := s.quota.Reserve(ctx, userID, uploadID, req.Size, plan.expiresAt); err != nil {
+		return nil, err
+	}
+
+	file := &models.File{
+		UUID:         uploadID,
+		UserID:       userID,
+		ParentID:     req.ParentID,
+		Name:         name,
+		Path:         parentPath,
+		MimeType:     &contentType,
+		Size:         req.Size,
+		Hash:         &hash,
+		HashType:     &hashType,
+		StorageType:  s.store.Type(),
+		StoragePath:  &key,
+		AccessLevel:  "private",
+		Status:       "uploading",
+		UploadStatus: "uploading",
+		Metadata:     plan.metadata(),
+	}
+	if ext := strings.TrimPrefix(path.Ext(name), "."); ext != "" {
+		ext = strings.ToLower(ext)
+		file.Extension = &ext
+	}
+	if err := s.fileRepo.Create(ctx, file); err != nil {
+		s.release(ctx, uploadID)
+		return nil, fmt.Errorf("登记上传文件失败: %w", err)
+	}
+	// 占位文件计入文件夹校验和，登记后目标文件夹的子项已变化
+	s.invalidateChecksums(ctx, file.ParentID)
+
+	if s.options.TTLTuner != nil {
+		s.options.TTLTuner.ObserveStarted(clientType)
+	}
+	s.publish(file, plan, UploadPhaseUploading, 0, 0, "")
+	s.logger.Info("Chunked upload initiated",
+		zap.Uint("user_id", userID),
+		zap.Uint("file_id", file.ID),
+		zap.String("upload_id", uploadID),
+		zap.Int64("size", req.Size),
+		zap.Int("total_chunks", totalChunks),
+		zap.String("client_type", clientType),
+		zap.Duration("ttl", ttl))
+
+	return newChunkedUploadSession(file, plan, nil), nil
+}
+
+// UploadChunk 上传单个分片，返回上传后的任务状态
+//
+// 分片流式写入存储并同时计算哈希，大小或哈希不一致时丢弃已写入的数据
+func (s *chunkedUploadService) UploadChunk(ctx context.Context, userID uint, uploadID string, chunk *ChunkUpload) (*ChunkedUploadSession, error) {
+	if chunk == nil || chunk.Data == nil {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "分片数据不能为空")
+	}
+
+	file, plan, err := s.loadUpload(ctx, userID, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	if chunk.Index < 0 || chunk.Index >= plan.totalChunks {
+		return nil, pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "分片索引必须在0到 %d 之间", plan.totalChunks-1)
+	}
+	expectedSize := plan.chunkLength(chunk.Index, file.Size)
+	if chunk.Size != expectedSize {
+		return nil, pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "分片 %d 大小应为 %d 字节", chunk.Index, expectedSize)
+	}
+	if strings.TrimSpace(chunk.Hash) == "" {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "分片哈希不能为空")
+	}
+
+	// 先延长有效期，分片写入期间上传不会被清理
+	plan = s.extendExpiry(ctx, file, plan)
+	chunkPath := chunkStoragePath(uploadID, chunk.Index)
+	if err := s.writeChunk(ctx, chunkPath, chunk, plan.hashAlgorithm, expectedSize); err != nil {
+		return nil, err
+	}
+
+	completedAt := s.clock.Now()
+	record := &models.FileUploadChunk{
+		UploadID:           uploadID,
+		UserID:             userID,
+		FileName:           file.Name,
+		FileSize:           file.Size,
+		FileHash:           derefString(file.Hash),
+		MimeType:           file.MimeType,
+		ChunkIndex:         chunk.Index,
+		ChunkSize:          expectedSize,
+		ChunkHash:          strings.ToLower(strings.TrimSpace(chunk.Hash)),
+		ChunkHashAlgorithm: plan.hashAlgorithm,
+		TotalChunks:        plan.totalChunks,
+		StoragePath:        chunkPath,
+		StorageType:        s.store.Type(),
+		Status:             "completed",
+		ExpiresAt:          plan.expiresAt,
+		CompletedAt:        &completedAt,
+	}
+	if err := s.chunkRepo.SaveChunk(ctx, record); err != nil {
+		return nil, fmt.Errorf("登记分片失败: %w", err)
+	}
+
+	chunks, err := s.chunkRepo.ListByUploadID(ctx, uploadID)
+	if err != nil {
+		return nil, fmt.Errorf("获取已上传分片失败: %w", err)
+	}
+	session := newChunkedUploadSession(file, plan, chunks)
+	s.publish(file, plan, UploadPhaseUploading, len(session.UploadedChunks), receivedBytes(chunks), "")
+	return session, nil
+}
+
+// GetSession 查询上传任务状态，用于断点续传
+func (s *chunkedUploadService) GetSession(ctx context.Context, userID uint, uploadID string) (*ChunkedUploadSession, error) {
+	file, plan, err := s.loadUpload(ctx, userID, uploadID)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks, err := s.chunkRepo.ListByUploadID(ctx, uploadID)
+	if err != nil {
+		return nil, fmt.Errorf("获取已上传分片失败: %w", err)
+	}
+	return newChunkedUploadSession(file, plan, chunks), nil
+}
+
+// Merge 合并全部分片为最终文件
+//
+// 重复调用是安全的：文件已激活时直接返回，存储用量只累计一次。
+// 分片不完整时返回验证错误；整体大小或哈希不一致时上传记录标记为失败
+func (s *chunkedUploadService) Merge(ctx context.Context, userID uint, uploadID string) (*models.File, error) {
+	file, err := s.getOwnedFile(ctx, userID, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	if file.IsActive() {
+		return file, nil
+	}
+	file, plan, err := s.loadUpload(ctx, userID, uploadID)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks, err := s.chunkRepo.ListByUploadID(ctx, uploadID)
+	if err != nil {
+		return nil, fmt.Errorf("获取已上传分片失败: %w", err)
+	}
+	mergeChunks := make([]MergeChunk, 0, len(chunks))
+	for _, chunk := range chunks {
+		if chunk.IsCompleted() {
+			mergeChunks = append(mergeChunks, MergeChunk{Index: chunk.ChunkIndex, Size: chunk.ChunkSize, StoragePath: chunk.StoragePath})
+		}
+	}
+	if len(mergeChunks) != plan.totalChunks {
+		return nil, pkgErrors.WrapErrorf(pkgErrors.ErrValidationFailed,
+			"分片不完整: 已上传 %d/%d", len(mergeChunks), plan.totalChunks)
+	}
+
+	s.publish(file, plan, UploadPhaseMerging, plan.totalChunks, file.Size, "")
+	result, err := s.merger.Merge(ctx, MergeRequest{
+		DestPath:     *file.StoragePath,
+		Chunks:       mergeChunks,
+		ExpectedSize: file.Size,
+		ExpectedHash: derefString(file.Hash),
+		HashType:     derefString(file.HashType),
+	})
+	if err != nil {
+		if errors.Is(err, pkgErrors.ErrFileCorrupted) {
+			// 分片已逐个校验，整体不一致说明声明的文件哈希有误，重试无法恢复
+			s.failUpload(ctx, file, plan, err.Error())
+			return nil, pkgErrors.WrapError(pkgErrors.ErrValidationFailed, err.Error())
+		}
+		return nil, fmt.Errorf("合并分片失败: %w", err)
+	}
+
+	completed, err := s.fileRepo.CompleteUpload(ctx, file.ID, result.Size)
+	if err != nil {
+		return nil, fmt.Errorf("激活上传文件失败: %w", err)
+	}
+	if completed {
+		if s.options.TTLTuner != nil {
+			var duration time.Duration
+			if !plan.startedAt.IsZero() {
+				duration = s.clock.Now().Sub(plan.startedAt)
+			}
+			s.options.TTLTuner.ObserveCompleted(plan.clientType, duration)
+		}
+		// 只有完成状态切换的那次合并提交预留，避免并发合并重复计算
+		if err := s.quota.Commit(ctx, userID, uploadID, result.Size); err != nil {
+			s.logger.Error("Failed to update storage usage after chunked upload",
+				zap.Uint("user_id", userID),
+				zap.Uint("file_id", file.ID),
+				zap.Error(err))
+		}
+		s.invalidateChecksums(ctx, file.ParentID)
+		s.logger.Info("Chunked upload merged",
+			zap.Uint("user_id", userID),
+			zap.Uint("file_id", file.ID),
+			zap.Int64("size", result.Size),
+			zap.Bool("composed", result.Composed))
+	}
+	s.removeChunks(ctx, uploadID, chunks)
+
+	file.Size = result.Size
+	file.Status = "active"
+	file.UploadStatus = "completed"
+	s.publish(file, plan, UploadPhaseCompleted, plan.totalChunks, file.Size, "")
+	return file, nil
+}
+
+// invalidateChecksums 使目标文件夹及其父链上的校验和失效，上传到根目录时无需处理，失败只记录日志
+func (s *chunkedUploadService) invalidateChecksums(ctx context.Context, parentID *uint) {
+	if s.options.Checksums == nil || parentID == nil {
+		return
+	}
+	if err := s.options.Checksums.InvalidateChecksums(ctx, *parentID); err != nil {
+		s.logger.Warn("Failed to invalidate folder checksums",
+			zap.Uint("folder_id", *parentID),
+			zap.Error(err))
+	}
+}
+
+// Abort 取消进行中的上传，删除已上传的分片和占位文件并释放预留
+//
+// 已合并或已失败的上传不能取消
+func (s *chunkedUploadService) Abort(ctx context.Context, userID uint, uploadID string) error {
+	file, err := s.getOwnedFile(ctx, userID, uploadID)
+	if err != nil {
+		return err
+	}
+	if file.Status != models.FileStatusUploading {
+		return pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "上传任务已结束")
+	}
+
+	// 先删除占位文件，与并发的合并竞争时只有一方成功
+	deleted, err := s.fileRepo.DeleteUpload(ctx, file)
+	if err != nil {
+		return fmt.Errorf("删除上传文件失败: %w", err)
+	}
+	if !deleted {
+		return pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "上传任务已结束")
+	}
+	s.release(ctx, uploadID)
+	if chunks, err := s.chunkRepo.ListByUploadID(ctx, uploadID); err == nil {
+		s.removeChunks(ctx, uploadID, chunks)
+	}
+
+	plan, _ := parseUploadPlan(file.Metadata)
+	s.publish(file, plan, UploadPhaseFailed, 0, 0, "上传已取消")
+	s.logger.Info("Chunked upload aborted",
+		zap.Uint("user_id", userID),
+		zap.Uint("file_id", file.ID))
+	return nil
+}
+
+// AttachProgress 为列表中的上传占位文件填充上传进度，其他文件不受影响
+func (s *chunkedUploadService) AttachProgress(ctx context.Context, files []*models.File) error {
+	for _, file := range files {
+		if !file.IsUploadPlaceholder() {
+			continue
+		}
+		plan, ok := parseUploadPlan(file.Metadata)
+		if !ok {
+			continue
+		}
+		chunks, err := s.chunkRepo.ListByUploadID(ctx, file.UUID)
+		if err != nil {
+			return fmt.Errorf("获取已上传分片失败: %w", err)
+		}
+		session := newChunkedUploadSession(file, plan, chunks)
+		file.UploadProgress = &models.FileUploadProgress{
+			ReceivedChunks: len(session.UploadedChunks),
+			TotalChunks:    plan.totalChunks,
+			ReceivedBytes:  receivedBytes(chunks),
+			TotalBytes:     file.Size,
+			ExpiresAt:      plan.expiresAt,
+		}
+	}
+	return nil
+}
+
+// CleanupExpired 清理过期未合并的上传，返回删除的分片数
+//
+// 分片对象和记录一并删除，对应的占位文件删除并释放预留；单次最多处理 CleanupBatch 个分片。
+// 没有上传任何分片的过期占位文件按创建时间另行查找
+func (s *chunkedUploadService) CleanupExpired(ctx context.Context) (int, error) {
+	now := s.clock.Now()
+	expired, err := s.chunkRepo.ListExpired(ctx, now, s.options.CleanupBatch)
+	if err != nil {
+		return 0, fmt.Errorf("查询过期分片失败: %w", err)
+	}
+
+	ids := make([]uint, 0, len(expired))
+	uploads := make(map[string]bool)
+	for _, chunk := range expired {
+		if err := s.store.Delete(ctx, chunk.StoragePath); err != nil {
+			s.logger.Warn("Failed to delete expired chunk",
+				zap.String("upload_id", chunk.UploadID),
+				zap.String("path", chunk.StoragePath),
+				zap.Error(err))
+			continue
+		}
+		ids = append(ids, chunk.ID)
+		uploads[chunk.UploadID] = true
+	}
+	if len(ids) > 0 {
+		if err := s.chunkRepo.DeleteByIDs(ctx, ids); err != nil {
+			return 0, fmt.Errorf("删除过期分片记录失败: %w", err)
+		}
+	}
+
+	var files []*models.File
+	for uploadID := range uploads {
+		file, err := s.fileRepo.GetByUUID(ctx, uploadID)
+		if err != nil {
+			continue
+		}
+		files = append(files, file)
+	}
+	// 上传有效期从申请时开始计算，创建时间早于最短有效期的占位文件可能已过期
+	minTTL := s.options.TTL
+	if s.options.TTLTuner != nil {
+		minTTL = min(minTTL, s.options.TTLTuner.MinTTL())
+	}
+	stale, err := s.fileRepo.ListStaleUploads(ctx, now.Add(-minTTL), s.options.CleanupBatch)
+	if err != nil {
+		return 0, fmt.Errorf("查询过期上传失败: %w", err)
+	}
+	for _, file := range stale {
+		if uploads[file.UUID] {
+			continue
+		}
+		if plan, ok := parseUploadPlan(file.Metadata); ok && now.Before(plan.expiresAt) {
+			continue
+		}
+		if chunks, err := s.chunkRepo.ListByUploadID(ctx, file.UUID); err == nil {
+			s.removeChunks(ctx, file.UUID, chunks)
+		}
+		uploads[file.UUID] = true
+		files = append(files, file)
+	}
+
+	discarded := 0
+	for _, file := range files {
+		if s.discardExpired(ctx, file) {
+			discarded++
+		}
+	}
+
+	if len(ids) > 0 || discarded > 0 {
+		s.logger.Info("Expired uploads cleaned up",
+			zap.Int("chunks", len(ids)),
+			zap.Int("uploads", discarded))
+	}
+	return len(ids), nil
+}
+
+// discardExpired 删除过期上传的占位文件并释放预留，返回是否删除
+func (s *chunkedUploadService) discardExpired(ctx context.Context, file *models.File) bool {
+	if !file.IsUploadPlaceholder() {
+		return false
+	}
+	deleted, err := s.fileRepo.DeleteUpload(ctx, file)
+	if err != nil {
+		s.logger.Warn("Failed to delete expired upload",
+			zap.String("upload_id", file.UUID),
+			zap.Error(err))
+		return false
+	}
+	if !deleted {
+		return false
+	}
+	s.release(ctx, file.UUID)
+	if s.options.TTLTuner != nil {
+		plan, _ := parseUploadPlan(file.Metadata)
+		s.options.TTLTuner.ObserveAbandoned(plan.clientType)
+	}
+	return true
+}
+
+// extendExpiry 上传仍在进行时滑动延长有效期，同步更新占位文件、已上传分片和空间预留
+//
+// 占位文件更新失败时沿用原有效期；分片和预留更新失败只记录日志，分片在下次收到分片时再次延长
+func (s *chunkedUploadService) extendExpiry(ctx context.Context, file *models.File, plan uploadPlan) uploadPlan {
+	if s.options.TTLTuner == nil {
+		return plan
+	}
+	expiresAt, ok := s.options.TTLTuner.Extend(plan.clientType, plan.startedAt, plan.expiresAt, s.clock.Now())
+	if !ok {
+		return plan
+	}
+
+	extended := plan
+	extended.expiresAt = expiresAt
+	metadata := extended.metadata()
+	if err := s.fileRepo.UpdateUploadMetadata(ctx, file.ID, metadata); err != nil {
+		s.logger.Warn("Failed to extend upload expiry",
+			zap.String("upload_id", file.UUID),
+			zap.Error(err))
+		return plan
+	}
+	file.Metadata = metadata
+	if err := s.chunkRepo.ExtendExpiry(ctx, file.UUID, expiresAt); err != nil {
+		s.logger.Warn("Failed to extend chunk expiry", zap.String("upload_id", file.UUID), zap.Error(err))
+	}
+	if err := s.quota.Reserve(ctx, file.UserID, file.UUID, file.Size, expiresAt); err != nil {
+		s.logger.Warn("Failed to extend storage reservation", zap.String("upload_id", file.UUID), zap.Error(err))
+	}
+	s.logger.Debug("Upload expiry extended",
+		zap.String("upload_id", file.UUID),
+		zap.Time("expires_at", expiresAt))
+	return extended
+}
+
+// writeChunk 流式写入分片并校验大小和哈希
+func (s *chunkedUploadService) writeChunk(ctx context.Context, chunkPath string, chunk *ChunkUpload, algorithm string, expectedSize int64) error {
+	writer, err := s.store.Create(ctx, chunkPath)
+	if err != nil {
+		return fmt.Errorf("创建分片存储失败: %w", err)
+	}
+
+	// 多读一个字节用于发现超出声明大小的请求体
+	written, err := VerifyChunk(writer, io.LimitReader(chunk.Data, expectedSize+1), algorithm, chunk.Hash)
+	if err == nil && written != expectedSize {
+		err = pkgErrors.WrapErrorf(pkgErrors.ErrValidationFailed,
+			"分片 %d 大小不一致: 期望 %d，实际 %d", chunk.Index, expectedSize, written)
+	}
+	if err != nil {
+		_ = writer.Abort()
+		if errors.Is(err, pkgErrors.ErrFileCorrupted) {
+			return pkgErrors.WrapError(pkgErrors.ErrValidationFailed, err.Error())
+		}
+		return err
+	}
+
+	if err := writer.Close(); err != nil {
+		_ = writer.Abort()
+		return fmt.Errorf("保存分片失败: %w", err)
+	}
+	return nil
+}
+
+// loadUpload 获取进行中的上传任务及其分片规划
+func (s *chunkedUploadService) loadUpload(ctx context.Context, userID uint, uploadID string) (*models.File, uploadPlan, error) {
+	file, err := s.getOwnedFile(ctx, userID, uploadID)
+	if err != nil {
+		return nil, uploadPlan{}, err
+	}
+	plan, ok := parseUploadPlan(file.Metadata)
+	if !ok || file.StoragePath == nil {
+		return nil, uploadPlan{}, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "分片上传任务不存在")
+	}
+	if file.Status != "uploading" {
+		return nil, uploadPlan{}, pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "上传任务已结束")
+	}
+	if !s.clock.Now().Before(plan.expiresAt) {
+		return nil, uploadPlan{}, pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "上传任务已过期，请重新上传")
+	}
+	return file, plan, nil
+}
+
+// getOwnedFile 获取当前用户的上传文件记录
+func (s *chunkedUploadService) getOwnedFile(ctx context.Context, userID uint, uploadID string) (*models.File, error) {
+	if userID == 0 || uploadID == "" {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "用户ID和上传任务ID不能为空")
+	}
+
+	file, err := s.fileRepo.GetByUUID(ctx, uploadID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "上传任务不存在")
+		}
+		return nil, fmt.Errorf("获取上传文件失败: %w", err)
+	}
+	if file.UserID != userID {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, "无权操作该上传任务")
+	}
+	return file, nil
+}
+
+// release 释放上传的空间预留，失败时留给预留过期清理
+func (s *chunkedUploadService) release(ctx context.Context, uploadID string) {
+	if err := s.quota.Release(ctx, uploadID); err != nil {
+		s.logger.Warn("Failed to release storage reservation", zap.String("upload_id", uploadID), zap.Error(err))
+	}
+}
+
+// failUpload 将上传标记为失败并删除分片
+func (s *chunkedUploadService) failUpload(ctx context.Context, file *models.File, plan uploadPlan, reason string) {
+	if err := s.fileRepo.FailUpload(ctx, file.ID); err != nil {
+		s.logger.Warn("Failed to mark chunked upload as failed", zap.Uint("file_id", file.ID), zap.Error(err))
+	}
+	s.release(ctx, file.UUID)
+	if chunks, err := s.chunkRepo.ListByUploadID(ctx, file.UUID); err == nil {
+		s.removeChunks(ctx, file.UUID, chunks)
+	}
+	s.publish(file, plan, UploadPhaseFailed, 0, 0, reason)
+	s.logger.Warn("Chunked upload verification failed",
+		zap.Uint("user_id", file.UserID),
+		zap.Uint("file_id", file.ID),
+		zap.String("reason", reason))
+}
+
+// removeChunks 删除上传任务的分片对象和记录，失败时留给过期清理
+func (s *chunkedUploadService) removeChunks(ctx context.Context, uploadID string, chunks []*models.FileUploadChunk) {
+	for _, chunk := range chunks {
+		if err := s.store.Delete(ctx, chunk.StoragePath); err != nil {
+			s.logger.Warn("Failed to delete merged chunk",
+				zap.String("upload_id", uploadID),
+				zap.String("path", chunk.StoragePath),
+				zap.Error(err))
+			return
+		}
+	}
+	if err := s.chunkRepo.DeleteByUploadID(ctx, uploadID); err != nil {
+		s.logger.Warn("Failed to delete chunk records", zap.String("upload_id", uploadID), zap.Error(err))
+	}
+}
+
+// publish 发布上传进度
+func (s *chunkedUploadService) publish(file *models.File, plan uploadPlan, phase string, received int, receivedBytes int64, message string) {
+	if s.progress == nil {
+		return
+	}
+	s.progress.Publish(UploadProgress{
+		UploadID:       file.UUID,
+		UserID:         file.UserID,
+		Phase:          phase,
+		ReceivedChunks: received,
+		TotalChunks:    plan.totalChunks,
+		ReceivedBytes:  receivedBytes,
+		TotalBytes:     file.Size,
+		Message:        message,
+		UpdatedAt:      s.clock.Now(),
+	})
+}
+
+// resolveParent 校验目标文件夹并返回新文件的路径
+func (s *chunkedUploadService) resolveParent(ctx context.Context, userID uint, parentID *uint) (string, error) {
+	if parentID == nil {
+		return "/", nil
+	}
+
+	parent, err := s.fileRepo.GetByID(ctx, *parentID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "目标文件夹不存在")
+		}
+		return "", fmt.Errorf("获取目标文件夹失败: %w", err)
+	}
+	if parent.UserID != userID {
+		return "", pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, "无权访问目标文件夹")
+	}
+	if !parent.IsFolder || !parent.IsActive() {
+		return "", pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "目标必须是有效的文件夹")
+	}
+	return parent.GetFullPath(), nil
+}
+
+// metadata 将分片规划转换为文件元数据
+func (p uploadPlan) metadata() *basemodels.JSONMap {
+	metadata := basemodels.JSONMap{
+		uploadMetaChunkSize:     p.chunkSize,
+		uploadMetaTotalChunks:   p.totalChunks,
+		uploadMetaHashAlgorithm: p.hashAlgorithm,
+		uploadMetaExpiresAt:     p.expiresAt.UTC().Format(time.RFC3339),
+	}
+	if !p.startedAt.IsZero() {
+		metadata[uploadMetaStartedAt] = p.startedAt.UTC().Format(time.RFC3339)
+	}
+	if p.clientType != "" {
+		metadata[uploadMetaClientType] = p.clientType
+	}
+	return &metadata
+}
+
+// chunkLength 返回指定分片的大小，最后一个分片为剩余字节数
+func (p uploadPlan) chunkLength(index int, fileSize int64) int64 {
+	if index == p.totalChunks-1 {
+		return fileSize - int64(index)*p.chunkSize
+	}
+	return p.chunkSize
+}
+
+// parseUploadPlan 从文件元数据读取分片规划，元数据经过JSON往返后数值为float64
+func parseUploadPlan(metadata *basemodels.JSONMap) (uploadPlan, bool) {
+	if metadata == nil {
+		return uploadPlan{}, false
+	}
+	meta := *metadata
+
+	chunkSize, ok1 := metaNumber(meta[uploadMetaChunkSize])
+	totalChunks, ok2 := metaNumber(meta[uploadMetaTotalChunks])
+	algorithm, ok3 := meta[uploadMetaHashAlgorithm].(string)
+	expiresRaw, ok4 := meta[uploadMetaExpiresAt].(string)
+	if !ok1 || !ok2 || !ok3 || !ok4 || chunkSize <= 0 || totalChunks <= 0 {
+		return uploadPlan{}, false
+	}
+	expiresAt, err := time.Parse(time.RFC3339, expiresRaw)
+	if err != nil {
+		return uploadPlan{}, false
+	}
+
+	plan := uploadPlan{
+		chunkSize:     chunkSize,
+		totalChunks:   int(totalChunks),
+		hashAlgorithm: algorithm,
+		expiresAt:     expiresAt,
+	}
+	// 申请时间和客户端类型是后来加入的，早期的上传记录没有
+	if startedRaw, ok := meta[uploadMetaStartedAt].(string); ok {
+		plan.startedAt, _ = time.Parse(time.RFC3339, startedRaw)
+	}
+	plan.clientType, _ = meta[uploadMetaClientType].(string)
+	return plan, true
+}
+
+// metaNumber 读取元数据中的整数
+func metaNumber(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return int64(v), true
+	case int64:
+		return v, true
+	case int:
+		return int64(v), true
+	default:
+		return 0, false
+	}
+}
+
+// newChunkedUploadSession 构建上传任务状态
+func newChunkedUploadSession(file *models.File, plan uploadPlan, chunks []*models.FileUploadChunk) *ChunkedUploadSession {
+	uploaded := make([]int, 0, len(chunks))
+	for _, chunk := range chunks {
+		if chunk.IsCompleted() {
+			uploaded = append(uploaded, chunk.ChunkIndex)
+		}
+	}
+	return &ChunkedUploadSession{
+		UploadID:           file.UUID,
+		FileID:             file.ID,
+		Name:               file.Name,
+		Size:               file.Size,
+		ChunkSize:          plan.chunkSize,
+		TotalChunks:        plan.totalChunks,
+		ChunkHashAlgorithm: plan.hashAlgorithm,
+		UploadedChunks:     uploaded,
+		ExpiresAt:          plan.expiresAt,
+	}
+}
+
+// receivedBytes 统计已接收分片的总字节数
+func receivedBytes(chunks []*models.FileUploadChunk) int64 {
+	var total int64
+	for _, chunk := range chunks {
+		if chunk.IsCompleted() {
+			total += chunk.ChunkSize
+		}
+	}
+	return total
+}
+
+// chunkPathPrefix 分片存储路径前缀
+const chunkPathPrefix = "chunks"
+
+// chunkStoragePath 返回分片的存储路径
+func chunkStoragePath(uploadID string, index int) string {
+	return path.Join(chunkPathPrefix, uploadID, fmt.Sprintf("%05d", index))
+}
+
+// derefString 返回字符串指针的值，nil时返回空字符串
+func derefString(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
@@ -0,0 +1,551 @@
+package file
+
+import (
+	"context"
+	"crypto/md5" // #nosec G501 - 测试分片校验
+	"encoding/hex"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/clock"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/idgen"
+	"cloudpan/internal/pkg/storage"
+	"cloudpan/internal/repository/models"
+)
+
+// memoryChunkRepository 内存分片仓储
+type memoryChunkRepository struct {
+	nextID uint
+	chunks map[uint]*models.FileUploadChunk
+}
+
+func newMemoryChunkRepository() *memoryChunkRepository {
+	return &memoryChunkRepository{chunks: make(map[uint]*models.FileUploadChunk)}
+}
+
+func (r *memoryChunkRepository) SaveChunk(_ context.Context, chunk *models.FileUploadChunk) error {
+	for id, existing := range r.chunks {
+		if existing.UploadID == chunk.UploadID && existing.ChunkIndex == chunk.ChunkIndex {
+			chunk.ID = id
+			r.chunks[id] = chunk
+			return nil
+		}
+	}
+	r.nextID++
+	chunk.ID = r.nextID
+	r.chunks[chunk.ID] = chunk
+	return nil
+}
+
+func (r *memoryChunkRepository) ListByUploadID(_ context.Context, uploadID string) ([]*models.FileUploadChunk, error) {
+	var result []*models.FileUploadChunk
+	for _, chunk := range r.chunks {
+		if chunk.UploadID == uploadID {
+			result = append(result, chunk)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ChunkIndex < result[j].ChunkIndex })
+	return result, nil
+}
+
+func (r *memoryChunkRepository) ExtendExpiry(_ context.Context, uploadID string, expiresAt time.Time) error {
+	for _, chunk := range r.chunks {
+		if chunk.UploadID == uploadID && chunk.ExpiresAt.Before(expiresAt) {
+			chunk.ExpiresAt = expiresAt
+		}
+	}
+	return nil
+}
+
+func (r *memoryChunkRepository) DeleteByUploadID(_ context.Context, uploadID string) error {
+	for id, chunk := range r.chunks {
+		if chunk.UploadID == uploadID {
+			delete(r.chunks, id)
+		}
+	}
+	return nil
+}
+
+func (r *memoryChunkRepository) ListExpired(_ context.Context, before time.Time, limit int) ([]*models.FileUploadChunk, error) {
+	var result []*models.FileUploadChunk
+	for _, chunk := range r.chunks {
+		if chunk.ExpiresAt.Before(before) && len(result) < limit {
+			result = append(result, chunk)
+		}
+	}
+	return result, nil
+}
+
+func (r *memoryChunkRepository) DeleteByIDs(_ context.Context, ids []uint) error {
+	for _, id := range ids {
+		delete(r.chunks, id)
+	}
+	return nil
+}
+
+func (r *memoryChunkRepository) ExistsByStoragePath(_ context.Context, storagePath string) (bool, error) {
+	for _, chunk := range r.chunks {
+		if chunk.StoragePath == storagePath {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryChunkRepository) DeleteByStoragePath(_ context.Context, storagePath string) (int64, error) {
+	var removed int64
+	for id, chunk := range r.chunks {
+		if chunk.StoragePath == storagePath {
+			delete(r.chunks, id)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+const chunkedTestContent = "hello world!"
+
+func md5Hex(data string) string {
+	sum := md5.Sum([]byte(data)) // #nosec G401
+	return hex.EncodeToString(sum[:])
+}
+
+type chunkedUploadFixture struct {
+	svc    *chunkedUploadService
+	repo   *MockFileRepository
+	quota  *MockQuotaAccountant
+	chunks *memoryChunkRepository
+	store  storage.Storage
+	file   *models.File
+	clock  *clock.Fake
+}
+
+// newChunkedUploadFixture 创建分片大小为4字节的服务，并申请一个12字节文件的上传
+func newChunkedUploadFixture(t *testing.T) *chunkedUploadFixture {
+	t.Helper()
+	store, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+
+	f := &chunkedUploadFixture{
+		repo:   new(MockFileRepository),
+		quota:  new(MockQuotaAccountant),
+		chunks: newMemoryChunkRepository(),
+		store:  store,
+		clock:  clock.NewFake(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)),
+	}
+	f.svc = NewChunkedUploadService(f.repo, f.chunks, f.quota, store, nil, nil,
+		ChunkedUploadOptions{ChunkSize: 4, MaxSize: 1024}, f.clock, idgen.NewSequence("upload"), zap.NewNop()).(*chunkedUploadService)
+
+	// 按声明大小预留到上传任务过期
+	f.quota.On("Reserve", mock.Anything, uint(7), "upload-000001", int64(12), f.clock.Now().Add(DefaultChunkUploadTTL)).Return(nil).Once()
+	f.repo.On("Create", mock.Anything, mock.AnythingOfType("*models.File")).Run(func(args mock.Arguments) {
+		f.file = args.Get(1).(*models.File)
+		f.file.ID = 42
+	}).Return(nil).Once()
+
+	session, err := f.svc.Initiate(context.Background(), 7, &ChunkedUploadRequest{
+		Name: "hello.txt",
+		Size: int64(len(chunkedTestContent)),
+		Hash: md5Hex(chunkedTestContent),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "upload-000001", session.UploadID)
+	assert.Equal(t, 3, session.TotalChunks)
+	assert.Equal(t, models.ChunkHashAlgorithmMD5, session.ChunkHashAlgorithm)
+	assert.Equal(t, "uploading", f.file.Status)
+	assert.Equal(t, f.file.UUID, f.quota.Calls[0].Arguments.String(2))
+
+	f.repo.On("GetByUUID", mock.Anything, f.file.UUID).Return(f.file, nil)
+	return f
+}
+
+func (f *chunkedUploadFixture) upload(t *testing.T, index int) (*ChunkedUploadSession, error) {
+	t.Helper()
+	data := chunkedTestContent[index*4 : index*4+4]
+	return f.svc.UploadChunk(context.Background(), 7, f.file.UUID, &ChunkUpload{
+		Index: index, Hash: md5Hex(data), Size: int64(len(data)), Data: strings.NewReader(data),
+	})
+}
+
+func TestChunkedUploadService_Initiate(t *testing.T) {
+	quota := new(MockQuotaAccountant)
+	svc := NewChunkedUploadService(new(MockFileRepository), newMemoryChunkRepository(), quota,
+		nil, nil, nil, ChunkedUploadOptions{ChunkSize: 4, MaxSize: 1024}, nil, nil, zap.NewNop())
+
+	t.Run("size exceeds limit", func(t *testing.T) {
+		_, err := svc.Initiate(context.Background(), 7, &ChunkedUploadRequest{Name: "a.bin", Size: 2048, Hash: "abc"})
+		assert.True(t, pkgErrors.IsValidationError(err))
+	})
+
+	t.Run("unsupported hash type", func(t *testing.T) {
+		_, err := svc.Initiate(context.Background(), 7, &ChunkedUploadRequest{Name: "a.bin", Size: 10, Hash: "abc", HashType: "crc64"})
+		assert.Error(t, err)
+	})
+
+	t.Run("quota exceeded", func(t *testing.T) {
+		quota.On("Reserve", mock.Anything, uint(7), mock.Anything, int64(10), mock.Anything).
+			Return(pkgErrors.WrapError(pkgErrors.ErrQuotaExceeded, "存储空间不足")).Once()
+
+		_, err := svc.Initiate(context.Background(), 7, &ChunkedUploadRequest{Name: "a.bin", Size: 10, Hash: "abc"})
+		assert.ErrorIs(t, err, pkgErrors.ErrQuotaExceeded)
+	})
+}
+
+func TestChunkedUploadService_ResumeAndMerge(t *testing.T) {
+	ctx := context.Background()
+	f := newChunkedUploadFixture(t)
+
+	_, err := f.upload(t, 2)
+	require.NoError(t, err)
+	session, err := f.upload(t, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []int{0, 2}, session.UploadedChunks)
+
+	// 中断后查询已上传分片
+	session, err = f.svc.GetSession(ctx, 7, f.file.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, []int{0, 2}, session.UploadedChunks)
+	assert.False(t, session.IsComplete())
+
+	_, err = f.svc.Merge(ctx, 7, f.file.UUID)
+	assert.True(t, pkgErrors.IsValidationError(err), "分片不完整时不能合并")
+
+	session, err = f.upload(t, 1)
+	require.NoError(t, err)
+	assert.True(t, session.IsComplete())
+
+	f.repo.On("CompleteUpload", mock.Anything, uint(42), int64(12)).Return(true, nil).Once()
+	f.quota.On("Commit", mock.Anything, uint(7), f.file.UUID, int64(12)).Return(nil).Once()
+
+	merged, err := f.svc.Merge(ctx, 7, f.file.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, "active", merged.Status)
+	assert.Equal(t, chunkedTestContent, readAll(t, f.store, *merged.StoragePath))
+
+	remaining, _ := f.chunks.ListByUploadID(ctx, f.file.UUID)
+	assert.Empty(t, remaining, "合并后应删除分片记录")
+	exists, err := f.store.Exists(ctx, chunkStoragePath(f.file.UUID, 0))
+	require.NoError(t, err)
+	assert.False(t, exists, "合并后应删除分片对象")
+
+	// 重复合并直接返回已激活的文件
+	again, err := f.svc.Merge(ctx, 7, f.file.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, merged.ID, again.ID)
+	f.repo.AssertExpectations(t)
+	f.quota.AssertExpectations(t)
+}
+
+func TestChunkedUploadService_InitiateInvalidatesFolderChecksums(t *testing.T) {
+	ctx := context.Background()
+	repo := new(MockFileRepository)
+	quota := new(MockQuotaAccountant)
+	checksums := &recordingChecksums{}
+	store, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	svc := NewChunkedUploadService(repo, newMemoryChunkRepository(), quota, store, nil, nil,
+		ChunkedUploadOptions{ChunkSize: 4, MaxSize: 1024, Checksums: checksums}, nil, nil, zap.NewNop())
+
+	parent := newTestFile(5, 7, nil, "docs", true)
+	parent.Status = "active"
+	repo.On("GetByID", mock.Anything, uint(5)).Return(parent, nil)
+	quota.On("Reserve", mock.Anything, uint(7), mock.Anything, int64(12), mock.Anything).Return(nil)
+	repo.On("Create", mock.Anything, mock.AnythingOfType("*models.File")).Return(nil)
+
+	_, err = svc.Initiate(ctx, 7, &ChunkedUploadRequest{Name: "a.bin", Size: 12, Hash: md5Hex(chunkedTestContent), ParentID: uintPtr(5)})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{5}, checksums.folderIDs, "占位文件计入文件夹校验和")
+}
+
+func TestChunkedUploadService_MergeInvalidatesFolderChecksums(t *testing.T) {
+	ctx := context.Background()
+	f := newChunkedUploadFixture(t)
+	f.svc.options.Checksums = NewFileService(f.repo, zap.NewNop())
+	f.file.ParentID = uintPtr(5)
+
+	// 目标文件夹和上级文件夹的校验和已经计算过
+	checksum := "cached"
+	folder := newTestFile(5, 7, uintPtr(1), "docs", true)
+	folder.ContentChecksum = &checksum
+	root := newTestFile(1, 7, nil, "root", true)
+	root.ContentChecksum = &checksum
+	f.repo.On("GetByID", mock.Anything, uint(5)).Return(folder, nil)
+	f.repo.On("GetByID", mock.Anything, uint(1)).Return(root, nil)
+	f.repo.On("ClearChecksums", mock.Anything, []uint{5, 1}).Return(nil).Once()
+
+	for i := 0; i < 3; i++ {
+		_, err := f.upload(t, i)
+		require.NoError(t, err)
+	}
+	f.repo.On("CompleteUpload", mock.Anything, uint(42), int64(12)).Return(true, nil).Once()
+	f.quota.On("Commit", mock.Anything, uint(7), f.file.UUID, int64(12)).Return(nil).Once()
+
+	_, err := f.svc.Merge(ctx, 7, f.file.UUID)
+	require.NoError(t, err)
+	f.repo.AssertExpectations(t)
+}
+
+func TestChunkedUploadService_UploadChunk(t *testing.T) {
+	t.Run("hash mismatch", func(t *testing.T) {
+		f := newChunkedUploadFixture(t)
+		_, err := f.svc.UploadChunk(context.Background(), 7, f.file.UUID, &ChunkUpload{
+			Index: 0, Hash: md5Hex("nope"), Size: 4, Data: strings.NewReader("hell"),
+		})
+		assert.True(t, pkgErrors.IsValidationError(err))
+
+		chunks, _ := f.chunks.ListByUploadID(context.Background(), f.file.UUID)
+		assert.Empty(t, chunks)
+	})
+
+	t.Run("wrong size", func(t *testing.T) {
+		f := newChunkedUploadFixture(t)
+		_, err := f.svc.UploadChunk(context.Background(), 7, f.file.UUID, &ChunkUpload{
+			Index: 0, Hash: md5Hex("hello"), Size: 5, Data: strings.NewReader("hello"),
+		})
+		assert.True(t, pkgErrors.IsValidationError(err))
+	})
+
+	t.Run("body longer than declared", func(t *testing.T) {
+		f := newChunkedUploadFixture(t)
+		_, err := f.svc.UploadChunk(context.Background(), 7, f.file.UUID, &ChunkUpload{
+			Index: 0, Hash: md5Hex("hell"), Size: 4, Data: strings.NewReader("hello"),
+		})
+		assert.True(t, pkgErrors.IsValidationError(err))
+	})
+
+	t.Run("other user", func(t *testing.T) {
+		f := newChunkedUploadFixture(t)
+		_, err := f.svc.UploadChunk(context.Background(), 8, f.file.UUID, &ChunkUpload{
+			Index: 0, Hash: md5Hex("hell"), Size: 4, Data: strings.NewReader("hell"),
+		})
+		assert.True(t, pkgErrors.IsPermissionError(err))
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		f := newChunkedUploadFixture(t)
+		f.clock.Advance(DefaultChunkUploadTTL)
+		_, err := f.upload(t, 0)
+		assert.True(t, pkgErrors.IsPermissionError(err))
+	})
+}
+
+func TestChunkedUploadService_MergeHashMismatch(t *testing.T) {
+	ctx := context.Background()
+	f := newChunkedUploadFixture(t)
+	bad := md5Hex("something else")
+	f.file.Hash = &bad
+	for i := 0; i < 3; i++ {
+		_, err := f.upload(t, i)
+		require.NoError(t, err)
+	}
+	f.repo.On("FailUpload", mock.Anything, uint(42)).Return(nil).Once()
+	f.quota.On("Release", mock.Anything, f.file.UUID).Return(nil).Once()
+
+	_, err := f.svc.Merge(ctx, 7, f.file.UUID)
+	assert.True(t, pkgErrors.IsValidationError(err))
+	f.repo.AssertNotCalled(t, "CompleteUpload", mock.Anything, mock.Anything, mock.Anything)
+	f.repo.AssertExpectations(t)
+	f.quota.AssertExpectations(t)
+}
+
+func TestChunkedUploadService_CleanupExpired(t *testing.T) {
+	ctx := context.Background()
+	f := newChunkedUploadFixture(t)
+	_, err := f.upload(t, 0)
+	require.NoError(t, err)
+
+	f.repo.On("ListStaleUploads", mock.Anything, mock.Anything, DefaultChunkCleanupBatch).Return(nil, nil)
+	removed, err := f.svc.CleanupExpired(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, removed, "未过期的分片不应清理")
+	f.repo.AssertNotCalled(t, "DeleteUpload", mock.Anything, mock.Anything)
+
+	f.clock.Advance(DefaultChunkUploadTTL + time.Minute)
+	f.repo.On("DeleteUpload", mock.Anything, f.file).Return(true, nil).Once()
+	f.quota.On("Release", mock.Anything, f.file.UUID).Return(nil).Once()
+
+	removed, err = f.svc.CleanupExpired(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	exists, err := f.store.Exists(ctx, chunkStoragePath(f.file.UUID, 0))
+	require.NoError(t, err)
+	assert.False(t, exists)
+	f.repo.AssertExpectations(t)
+	f.quota.AssertExpectations(t)
+}
+
+func TestChunkedUploadService_CleanupExpiredWithoutChunks(t *testing.T) {
+	ctx := context.Background()
+	f := newChunkedUploadFixture(t)
+
+	// 没有上传任何分片的占位文件按创建时间查找，上传未过期时保留
+	f.repo.On("ListStaleUploads", mock.Anything, mock.Anything, DefaultChunkCleanupBatch).Return([]*models.File{f.file}, nil)
+	_, err := f.svc.CleanupExpired(ctx)
+	require.NoError(t, err)
+	f.repo.AssertNotCalled(t, "DeleteUpload", mock.Anything, mock.Anything)
+
+	f.clock.Advance(DefaultChunkUploadTTL + time.Minute)
+	f.repo.On("DeleteUpload", mock.Anything, f.file).Return(true, nil).Once()
+	f.quota.On("Release", mock.Anything, f.file.UUID).Return(nil).Once()
+
+	removed, err := f.svc.CleanupExpired(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, removed)
+	f.repo.AssertCalled(t, "DeleteUpload", mock.Anything, f.file)
+	f.quota.AssertExpectations(t)
+}
+
+func TestChunkedUploadService_Abort(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("removes chunks and placeholder", func(t *testing.T) {
+		f := newChunkedUploadFixture(t)
+		_, err := f.upload(t, 0)
+		require.NoError(t, err)
+		f.repo.On("DeleteUpload", mock.Anything, f.file).Return(true, nil).Once()
+		f.quota.On("Release", mock.Anything, f.file.UUID).Return(nil).Once()
+
+		require.NoError(t, f.svc.Abort(ctx, 7, f.file.UUID))
+		assert.Empty(t, f.chunks.chunks)
+		exists, err := f.store.Exists(ctx, chunkStoragePath(f.file.UUID, 0))
+		require.NoError(t, err)
+		assert.False(t, exists)
+		f.repo.AssertExpectations(t)
+		f.quota.AssertExpectations(t)
+	})
+
+	t.Run("other user", func(t *testing.T) {
+		f := newChunkedUploadFixture(t)
+		err := f.svc.Abort(ctx, 8, f.file.UUID)
+		assert.True(t, pkgErrors.IsPermissionError(err))
+	})
+
+	t.Run("merged concurrently", func(t *testing.T) {
+		f := newChunkedUploadFixture(t)
+		_, err := f.upload(t, 0)
+		require.NoError(t, err)
+		f.repo.On("DeleteUpload", mock.Anything, f.file).Return(false, nil).Once()
+
+		err = f.svc.Abort(ctx, 7, f.file.UUID)
+		assert.ErrorIs(t, err, pkgErrors.ErrOperationNotAllowed)
+		assert.Len(t, f.chunks.chunks, 1, "上传已结束时不删除分片")
+		f.quota.AssertNotCalled(t, "Release", mock.Anything, mock.Anything)
+	})
+}
+
+func TestChunkedUploadService_AttachProgress(t *testing.T) {
+	f := newChunkedUploadFixture(t)
+	_, err := f.upload(t, 0)
+	require.NoError(t, err)
+	_, err = f.upload(t, 2)
+	require.NoError(t, err)
+
+	active := &models.File{Name: "done.txt", Status: models.FileStatusActive}
+	require.NoError(t, f.svc.AttachProgress(context.Background(), []*models.File{active, f.file}))
+
+	assert.Nil(t, active.UploadProgress)
+	require.NotNil(t, f.file.UploadProgress)
+	assert.Equal(t, 2, f.file.UploadProgress.ReceivedChunks)
+	assert.Equal(t, 3, f.file.UploadProgress.TotalChunks)
+	assert.Equal(t, int64(8), f.file.UploadProgress.ReceivedBytes)
+	assert.Equal(t, int64(12), f.file.UploadProgress.TotalBytes)
+	assert.Equal(t, f.clock.Now().Add(DefaultChunkUploadTTL), f.file.UploadProgress.ExpiresAt)
+}
+
+func TestChunkedUploadService_AdaptiveTTL(t *testing.T) {
+	ctx := context.Background()
+	f := newChunkedUploadFixture(t)
+	tuner := NewUploadTTLTuner(UploadTTLOptions{Default: 24 * time.Hour, Max: 48 * time.Hour, IdleTimeout: 6 * time.Hour})
+	f.svc.options.TTLTuner = tuner
+	started := f.clock.Now()
+	f.file.Metadata = uploadPlan{chunkSize: 4, totalChunks: 3, hashAlgorithm: models.ChunkHashAlgorithmMD5,
+		expiresAt: started.Add(24 * time.Hour), startedAt: started, clientType: UploadClientMobile}.metadata()
+
+	_, err := f.upload(t, 0)
+	require.NoError(t, err)
+
+	// 剩余有效期不足时滑动延长，已上传分片和预留同步延长
+	f.clock.Advance(22 * time.Hour)
+	extended := started.Add(28 * time.Hour)
+	f.repo.On("UpdateUploadMetadata", mock.Anything, uint(42), mock.AnythingOfType("*models.JSONMap")).Return(nil).Once()
+	f.quota.On("Reserve", mock.Anything, uint(7), f.file.UUID, int64(12), extended).Return(nil).Once()
+	session, err := f.upload(t, 1)
+	require.NoError(t, err)
+	assert.Equal(t, extended, session.ExpiresAt)
+	for _, chunk := range f.chunks.chunks {
+		assert.Equal(t, extended, chunk.ExpiresAt)
+	}
+
+	// 原有效期过后仍可继续上传
+	f.clock.Advance(3 * time.Hour)
+	_, err = f.upload(t, 2)
+	require.NoError(t, err)
+
+	f.repo.On("CompleteUpload", mock.Anything, uint(42), int64(12)).Return(true, nil).Once()
+	f.quota.On("Commit", mock.Anything, uint(7), f.file.UUID, int64(12)).Return(nil).Once()
+	_, err = f.svc.Merge(ctx, 7, f.file.UUID)
+	require.NoError(t, err)
+
+	metrics := tuner.Metrics()
+	require.Len(t, metrics, 1)
+	assert.Equal(t, UploadClientMobile, metrics[0].ClientType)
+	assert.Equal(t, int64(1), metrics[0].Completed)
+	assert.Equal(t, int64(1), metrics[0].Extended)
+	assert.Equal(t, "25h0m0s", metrics[0].MedianDuration)
+	f.repo.AssertExpectations(t)
+	f.quota.AssertExpectations(t)
+}
+
+func TestChunkedUploadService_AdaptiveTTLAbandoned(t *testing.T) {
+	ctx := context.Background()
+	f := newChunkedUploadFixture(t)
+	tuner := NewUploadTTLTuner(UploadTTLOptions{Min: 2 * time.Hour, IdleTimeout: 6 * time.Hour})
+	f.svc.options.TTLTuner = tuner
+
+	// 清理按最短有效期查找可能过期的占位文件
+	f.clock.Advance(DefaultChunkUploadTTL + time.Minute)
+	f.repo.On("ListStaleUploads", mock.Anything, f.clock.Now().Add(-2*time.Hour), DefaultChunkCleanupBatch).Return([]*models.File{f.file}, nil)
+	f.repo.On("DeleteUpload", mock.Anything, f.file).Return(true, nil).Once()
+	f.quota.On("Release", mock.Anything, f.file.UUID).Return(nil).Once()
+
+	_, err := f.svc.CleanupExpired(ctx)
+	require.NoError(t, err)
+	metrics := tuner.Metrics()
+	require.Len(t, metrics, 1)
+	assert.Equal(t, UploadClientOther, metrics[0].ClientType)
+	assert.Equal(t, int64(1), metrics[0].Abandoned)
+	assert.Equal(t, 1.0, metrics[0].AbandonRate)
+	f.repo.AssertCalled(t, "DeleteUpload", mock.Anything, f.file)
+	f.quota.AssertExpectations(t)
+}
+
+func TestChunkedUploadService_InitiateAdaptiveTTL(t *testing.T) {
+	quota := new(MockQuotaAccountant)
+	repo := new(MockFileRepository)
+	store, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	tuner := NewUploadTTLTuner(UploadTTLOptions{Adaptive: true, MinSamples: 1, Min: 2 * time.Hour})
+	tuner.ObserveCompleted(UploadClientDesktop, 20*time.Minute)
+	clk := clock.NewFake(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+	svc := NewChunkedUploadService(repo, newMemoryChunkRepository(), quota, store, nil, nil,
+		ChunkedUploadOptions{ChunkSize: 4, MaxSize: 1024, TTLTuner: tuner}, clk, idgen.NewSequence("upload"), zap.NewNop())
+
+	// 完成较快的客户端使用较短的有效期
+	quota.On("Reserve", mock.Anything, uint(7), "upload-000001", int64(12), clk.Now().Add(2*time.Hour)).Return(nil).Once()
+	repo.On("Create", mock.Anything, mock.AnythingOfType("*models.File")).Return(nil).Once()
+	session, err := svc.Initiate(context.Background(), 7, &ChunkedUploadRequest{
+		Name: "hello.txt", Size: 12, Hash: md5Hex(chunkedTestContent), ClientType: UploadClientDesktop,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, clk.Now().Add(2*time.Hour), session.ExpiresAt)
+	assert.Equal(t, int64(1), tuner.Metrics()[0].Started)
+	quota.AssertExpectations(t)
+}
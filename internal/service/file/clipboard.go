@@ -0,0 +1,402 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/cache"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	filerepo "cloudpan/internal/repository/file"
+	"cloudpan/internal/repository/models"
+)
+
+// MaxClipboardItems 剪贴板最多容纳的文件数
+const MaxClipboardItems = 500
+
+// 剪贴板操作类型
+const (
+	ClipboardCopy = "copy" // 复制，粘贴后剪贴板保留，可多次粘贴
+	ClipboardMove = "move" // 剪切，全部粘贴成功后清空剪贴板
+)
+
+// 粘贴冲突原因
+const (
+	ClipboardConflictMissing    = "missing"     // 文件已删除、不再属于当前用户或当前不可操作
+	ClipboardConflictNameExists = "name_exists" // 目标文件夹下已有同名文件(仅剪切)
+	ClipboardConflictIntoSelf   = "into_self"   // 文件夹不能粘贴到自身或其子文件夹中
+	ClipboardConflictFailed     = "failed"      // 检查通过但执行移动或复制时失败
+)
+
+// ClipboardService 服务端剪贴板接口
+//
+// 剪贴板按用户保存在 ClipboardStore 中(Redis可用时跨设备和会话共享)，支持"在一台设备上剪切、在另一台设备上粘贴"：
+// 1. 写入：保存操作类型和文件ID，文件必须属于当前用户且可用，整体覆盖之前的内容
+// 2. 查看：返回剪贴板中仍然可用的文件，已删除或不可用的文件ID单独列出
+// 3. 粘贴：先检查全部项目，存在冲突时不做任何修改并返回 ClipboardConflictError；
+// 检查通过后逐个移动或复制，个别项目执行失败时继续处理其余项目
+//
+// 剪贴板中同时包含文件夹及其子项时只粘贴文件夹，子项随之移动或复制；
+// 剪切全部粘贴成功后清空剪贴板，部分失败时剪贴板只保留失败的项目
+//
+// 使用示例：
+//
+//	service := NewClipboardService(fileRepo, folderRepo, treeService, cache.DefaultClipboardStore(), logger)
+//	clipboard, err := service.Set(ctx, userID, ClipboardMove, []uint{3, 5})
+//	result, err := service.Paste(ctx, userID, &targetID)
+type ClipboardService interface {
+	Get(ctx context.Context, userID uint) (*ClipboardView, error)
+	Set(ctx context.Context, userID uint, operation string, fileIDs []uint) (*ClipboardView, error)
+	Clear(ctx context.Context, userID uint) error
+	Paste(ctx context.Context, userID uint, targetParentID *uint) (*PasteResult, error)
+}
+
+// ClipboardView 剪贴板内容
+type ClipboardView struct {
+	Operation string         `json:"operation,omitempty"`  // 操作类型(copy/move)，剪贴板为空时省略
+	Items     []*models.File `json:"items"`                // 仍然可用的文件
+	Missing   []uint         `json:"missing"`              // 已删除或当前不可用的文件ID
+	UpdatedAt *time.Time     `json:"updated_at,omitempty"` // 写入时间
+	ExpiresAt *time.Time     `json:"expires_at,omitempty"` // 过期时间，不过期时省略
+}
+
+// PasteResult 粘贴结果
+type PasteResult struct {
+	Operation string              `json:"operation"` // 操作类型(copy/move)
+	Files     []*models.File      `json:"files"`     // 移动后或复制出的文件
+	Nested    []uint              `json:"nested"`    // 随上级文件夹一起粘贴的文件ID
+	Failed    []ClipboardConflict `json:"failed"`    // 执行失败的项目
+}
+
+// ClipboardConflict 无法粘贴的项目
+type ClipboardConflict struct {
+	FileID  uint   `json:"file_id"`        // 文件ID
+	Name    string `json:"name,omitempty"` // 文件名，文件已不存在时省略
+	Reason  string `json:"reason"`         // 冲突原因
+	Message string `json:"message"`        // 说明
+}
+
+// ClipboardConflictError 粘贴前检查发现冲突，未做任何修改
+type ClipboardConflictError struct {
+	Conflicts []ClipboardConflict `json:"conflicts"` // 全部冲突项目
+}
+
+// Error 返回错误信息
+func (e *ClipboardConflictError) Error() string {
+	return fmt.Sprintf("剪贴板中有 %d 个项目无法粘贴到目标文件夹", len(e.Conflicts))
+}
+
+// Unwrap 归类为资源冲突
+func (e *ClipboardConflictError) Unwrap() error {
+	return pkgErrors.ErrResourceExists
+}
+
+// clipboardService 服务端剪贴板实现
+type clipboardService struct {
+	fileRepo   filerepo.FileRepository
+	folderRepo filerepo.FolderRepository
+	tree       TreeService
+	store      cache.ClipboardStore
+	logger     *zap.Logger
+	now        func() time.Time
+}
+
+// NewClipboardService 创建服务端剪贴板服务
+func NewClipboardService(fileRepo filerepo.FileRepository, folderRepo filerepo.FolderRepository, tree TreeService, store cache.ClipboardStore, logger *zap.Logger) ClipboardService {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &clipboardService{
+		fileRepo:   fileRepo,
+		folderRepo: folderRepo,
+		tree:       tree,
+		store:      store,
+		logger:     logger,
+		now:        time.Now,
+	}
+}
+
+// Get 返回剪贴板内容，剪贴板为空或已过期时 Items 为空
+func (s *clipboardService) Get(ctx context.Context, userID uint) (*ClipboardView, error) {
+	clipboard, err := s.load(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	view := &ClipboardView{Items: []*models.File{}, Missing: []uint{}}
+	if clipboard == nil {
+		return view, nil
+	}
+
+	view.Operation = clipboard.Operation
+	view.UpdatedAt = &clipboard.UpdatedAt
+	view.ExpiresAt = clipboard.ExpiresAt
+	for _, id := range clipboard.FileIDs {
+		file, reason, err := s.lookup(ctx, userID, uint(id))
+		if err != nil {
+			return nil, err
+		}
+		if reason != "" {
+			view.Missing = append(view.Missing, uint(id))
+			continue
+		}
+		view.Items = append(view.Items, file)
+	}
+	return view, nil
+}
+
+// Set 覆盖剪贴板，重复的文件ID只保留一个
+func (s *clipboardService) Set(ctx context.Context, userID uint, operation string, fileIDs []uint) (*ClipboardView, error) {
+	if userID == 0 {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "用户ID不能为空")
+	}
+	if operation != ClipboardCopy && operation != ClipboardMove {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "操作类型必须是copy或move")
+	}
+	if len(fileIDs) == 0 {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "文件ID不能为空")
+	}
+
+	seen := make(map[uint]bool, len(fileIDs))
+	ids := make([]uint64, 0, len(fileIDs))
+	items := make([]*models.File, 0, len(fileIDs))
+	for _, id := range fileIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if len(ids) >= MaxClipboardItems {
+			return nil, pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "剪贴板最多容纳%d个文件", MaxClipboardItems)
+		}
+		file, reason, err := s.lookup(ctx, userID, id)
+		if err != nil {
+			return nil, err
+		}
+		if reason != "" {
+			return nil, pkgErrors.WrapErrorf(pkgErrors.ErrResourceNotFound, "文件 %d 不存在或当前不可操作", id)
+		}
+		ids = append(ids, uint64(id))
+		items = append(items, file)
+	}
+
+	clipboard := &cache.Clipboard{Operation: operation, FileIDs: ids, UpdatedAt: s.now()}
+	if err := s.store.Set(ctx, uint64(userID), clipboard); err != nil {
+		return nil, fmt.Errorf("保存剪贴板失败: %w", err)
+	}
+	s.logger.Debug("Clipboard updated",
+		zap.Uint("user_id", userID),
+		zap.String("operation", operation),
+		zap.Int("files", len(ids)))
+	return &ClipboardView{
+		Operation: operation,
+		Items:     items,
+		Missing:   []uint{},
+		UpdatedAt: &clipboard.UpdatedAt,
+		ExpiresAt: clipboard.ExpiresAt,
+	}, nil
+}
+
+// Clear 清空剪贴板
+func (s *clipboardService) Clear(ctx context.Context, userID uint) error {
+	if userID == 0 {
+		return pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "用户ID不能为空")
+	}
+	if err := s.store.Clear(ctx, uint64(userID)); err != nil {
+		return fmt.Errorf("清空剪贴板失败: %w", err)
+	}
+	return nil
+}
+
+// Paste 将剪贴板中的文件移动或复制到目标文件夹，targetParentID为nil表示根目录
+func (s *clipboardService) Paste(ctx context.Context, userID uint, targetParentID *uint) (*PasteResult, error) {
+	clipboard, err := s.load(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if clipboard == nil || len(clipboard.FileIDs) == 0 {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "剪贴板为空")
+	}
+	ancestors, err := s.targetAncestors(ctx, userID, targetParentID)
+	if err != nil {
+		return nil, err
+	}
+
+	var conflicts []ClipboardConflict
+	items := make([]*models.File, 0, len(clipboard.FileIDs))
+	for _, id := range clipboard.FileIDs {
+		file, reason, err := s.lookup(ctx, userID, uint(id))
+		if err != nil {
+			return nil, err
+		}
+		if reason != "" {
+			conflicts = append(conflicts, ClipboardConflict{FileID: uint(id), Reason: reason, Message: "文件不存在或当前不可操作"})
+			continue
+		}
+		items = append(items, file)
+	}
+
+	result := &PasteResult{Operation: clipboard.Operation, Files: []*models.File{}, Nested: []uint{}, Failed: []ClipboardConflict{}}
+	items, result.Nested = pruneNested(items)
+
+	names := make(map[string]uint, len(items))
+	for _, file := range items {
+		if file.IsFolder && ancestors[file.ID] {
+			conflicts = append(conflicts, ClipboardConflict{FileID: file.ID, Name: file.Name, Reason: ClipboardConflictIntoSelf, Message: "不能将文件夹粘贴到其自身或子文件夹中"})
+			continue
+		}
+		if clipboard.Operation != ClipboardMove || sameParent(file.ParentID, targetParentID) {
+			continue
+		}
+		exists, err := s.folderRepo.NameExists(ctx, userID, targetParentID, file.Name, file.ID)
+		if err != nil {
+			return nil, fmt.Errorf("检查文件名失败: %w", err)
+		}
+		if _, duplicated := names[file.Name]; exists || duplicated {
+			conflicts = append(conflicts, ClipboardConflict{FileID: file.ID, Name: file.Name, Reason: ClipboardConflictNameExists, Message: "目标文件夹下已存在同名文件"})
+			continue
+		}
+		names[file.Name] = file.ID
+	}
+	if len(conflicts) > 0 {
+		return nil, &ClipboardConflictError{Conflicts: conflicts}
+	}
+
+	var remaining []uint64
+	for _, file := range items {
+		var pasted *models.File
+		if clipboard.Operation == ClipboardMove {
+			pasted, err = s.tree.Move(ctx, userID, file.ID, targetParentID)
+		} else {
+			pasted, err = s.tree.Copy(ctx, userID, file.ID, targetParentID)
+		}
+		if err != nil {
+			s.logger.Warn("Failed to paste clipboard item",
+				zap.Uint("user_id", userID),
+				zap.Uint("file_id", file.ID),
+				zap.String("operation", clipboard.Operation),
+				zap.Error(err))
+			result.Failed = append(result.Failed, ClipboardConflict{FileID: file.ID, Name: file.Name, Reason: ClipboardConflictFailed, Message: err.Error()})
+			remaining = append(remaining, uint64(file.ID))
+			continue
+		}
+		result.Files = append(result.Files, pasted)
+	}
+
+	if clipboard.Operation == ClipboardMove {
+		s.consume(ctx, userID, clipboard, remaining)
+	}
+	s.logger.Info("Clipboard pasted",
+		zap.Uint("user_id", userID),
+		zap.String("operation", clipboard.Operation),
+		zap.Int("pasted", len(result.Files)),
+		zap.Int("failed", len(result.Failed)))
+	return result, nil
+}
+
+// consume 剪切粘贴后清空剪贴板，remaining 非空时只保留执行失败的项目；失败只记录日志
+func (s *clipboardService) consume(ctx context.Context, userID uint, clipboard *cache.Clipboard, remaining []uint64) {
+	var err error
+	if len(remaining) == 0 {
+		err = s.store.Clear(ctx, uint64(userID))
+	} else {
+		err = s.store.Set(ctx, uint64(userID), &cache.Clipboard{Operation: clipboard.Operation, FileIDs: remaining, UpdatedAt: s.now()})
+	}
+	if err != nil {
+		s.logger.Warn("Failed to update clipboard after paste", zap.Uint("user_id", userID), zap.Error(err))
+	}
+}
+
+// load 读取用户的剪贴板，为空或已过期时返回nil
+func (s *clipboardService) load(ctx context.Context, userID uint) (*cache.Clipboard, error) {
+	if userID == 0 {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "用户ID不能为空")
+	}
+	clipboard, err := s.store.Get(ctx, uint64(userID))
+	if err != nil {
+		return nil, fmt.Errorf("读取剪贴板失败: %w", err)
+	}
+	return clipboard, nil
+}
+
+// lookup 获取剪贴板中的文件，文件不存在、不属于该用户或不可用时返回 ClipboardConflictMissing
+func (s *clipboardService) lookup(ctx context.Context, userID, fileID uint) (*models.File, string, error) {
+	file, err := s.fileRepo.GetByID(ctx, fileID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ClipboardConflictMissing, nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("获取文件失败: %w", err)
+	}
+	if file.UserID != userID || !file.IsActive() {
+		return nil, ClipboardConflictMissing, nil
+	}
+	return file, "", nil
+}
+
+// targetAncestors 校验目标文件夹并返回目标及其全部上级文件夹的ID，目标为根目录时返回空集合
+func (s *clipboardService) targetAncestors(ctx context.Context, userID uint, targetParentID *uint) (map[uint]bool, error) {
+	ancestors := make(map[uint]bool)
+	if targetParentID == nil {
+		return ancestors, nil
+	}
+
+	target, err := s.fileRepo.GetByID(ctx, *targetParentID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "目标文件夹不存在")
+		}
+		return nil, fmt.Errorf("获取目标文件夹失败: %w", err)
+	}
+	if target.UserID != userID {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, "无权访问目标文件夹")
+	}
+	if !target.IsFolder || !target.IsActive() {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "目标必须是有效的文件夹")
+	}
+
+	current := target
+	for depth := 0; ; depth++ {
+		ancestors[current.ID] = true
+		if current.ParentID == nil || depth >= maxFolderDepth {
+			break
+		}
+		current, err = s.fileRepo.GetByID(ctx, *current.ParentID)
+		if err != nil {
+			return nil, fmt.Errorf("获取目标文件夹的上级失败: %w", err)
+		}
+	}
+	return ancestors, nil
+}
+
+// pruneNested 去掉位于剪贴板中其他文件夹下的项目，返回需要粘贴的项目和被去掉的文件ID
+func pruneNested(items []*models.File) ([]*models.File, []uint) {
+	var prefixes []string
+	for _, file := range items {
+		if file.IsFolder {
+			prefixes = append(prefixes, strings.TrimSuffix(file.GetFullPath(), "/")+"/")
+		}
+	}
+
+	top := make([]*models.File, 0, len(items))
+	nested := []uint{}
+	for _, file := range items {
+		fullPath := file.GetFullPath()
+		inside := false
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(fullPath, prefix) {
+				inside = true
+				break
+			}
+		}
+		if inside {
+			nested = append(nested, file.ID)
+			continue
+		}
+		top = append(top, file)
+	}
+	return top, nested
+}
@@ -0,0 +1,174 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"cloudpan/internal/pkg/cache"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/repository/models"
+)
+
+// newClipboardFixture 在 newTreeFixture 的目录树上创建剪贴板服务
+func newClipboardFixture(t *testing.T) (*treeFixture, ClipboardService, *cache.MemoryClipboardStore) {
+	f := newTreeFixture(t)
+	store := cache.NewMemoryClipboardStore(time.Hour)
+	return f, NewClipboardService(f.folders, f.folders, f.service, store, nil), store
+}
+
+func TestClipboardService_Set(t *testing.T) {
+	ctx := context.Background()
+	_, svc, store := newClipboardFixture(t)
+
+	_, err := svc.Set(ctx, 7, "cut", []uint{2})
+	assert.True(t, pkgErrors.IsValidationError(err))
+	_, err = svc.Set(ctx, 7, ClipboardMove, nil)
+	assert.True(t, pkgErrors.IsValidationError(err))
+	_, err = svc.Set(ctx, 8, ClipboardMove, []uint{2})
+	assert.True(t, pkgErrors.IsNotFoundError(err), "其他用户的文件不能放入剪贴板")
+
+	view, err := svc.Set(ctx, 7, ClipboardMove, []uint{2, 3, 2})
+	require.NoError(t, err)
+	assert.Equal(t, ClipboardMove, view.Operation)
+	require.Len(t, view.Items, 2)
+	assert.NotNil(t, view.ExpiresAt)
+
+	clipboard, err := store.Get(ctx, 7)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{2, 3}, clipboard.FileIDs, "重复的文件ID只保留一个")
+}
+
+func TestClipboardService_Get(t *testing.T) {
+	ctx := context.Background()
+	f, svc, _ := newClipboardFixture(t)
+
+	view, err := svc.Get(ctx, 7)
+	require.NoError(t, err)
+	assert.Empty(t, view.Operation)
+	assert.Empty(t, view.Items)
+
+	_, err = svc.Set(ctx, 7, ClipboardCopy, []uint{2, 4})
+	require.NoError(t, err)
+	f.folders.files[4].Status = "deleted"
+
+	view, err = svc.Get(ctx, 7)
+	require.NoError(t, err)
+	assert.Equal(t, ClipboardCopy, view.Operation)
+	require.Len(t, view.Items, 1)
+	assert.Equal(t, uint(2), view.Items[0].ID)
+	assert.Equal(t, []uint{4}, view.Missing)
+
+	require.NoError(t, svc.Clear(ctx, 7))
+	view, err = svc.Get(ctx, 7)
+	require.NoError(t, err)
+	assert.Empty(t, view.Items)
+}
+
+func TestClipboardService_Paste(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("empty clipboard", func(t *testing.T) {
+		_, svc, _ := newClipboardFixture(t)
+		_, err := svc.Paste(ctx, 7, uintPtr(5))
+		assert.True(t, pkgErrors.IsNotFoundError(err))
+	})
+
+	t.Run("move clears clipboard", func(t *testing.T) {
+		f, svc, store := newClipboardFixture(t)
+		_, err := svc.Set(ctx, 7, ClipboardMove, []uint{2, 3})
+		require.NoError(t, err)
+
+		result, err := svc.Paste(ctx, 7, uintPtr(5))
+		require.NoError(t, err)
+		require.Len(t, result.Files, 2)
+		assert.Empty(t, result.Failed)
+		assert.Equal(t, "/archive/a.txt", f.folders.files[2].GetFullPath())
+		assert.Equal(t, "/archive/sub/b.txt", f.folders.files[4].GetFullPath())
+
+		clipboard, err := store.Get(ctx, 7)
+		require.NoError(t, err)
+		assert.Nil(t, clipboard, "剪切粘贴后清空剪贴板")
+	})
+
+	t.Run("nested items move with folder", func(t *testing.T) {
+		f, svc, _ := newClipboardFixture(t)
+		_, err := svc.Set(ctx, 7, ClipboardMove, []uint{4, 1})
+		require.NoError(t, err)
+
+		result, err := svc.Paste(ctx, 7, uintPtr(5))
+		require.NoError(t, err)
+		require.Len(t, result.Files, 1)
+		assert.Equal(t, []uint{4}, result.Nested)
+		assert.Equal(t, "/archive/docs/sub/b.txt", f.folders.files[4].GetFullPath())
+	})
+
+	t.Run("conflicts leave files untouched", func(t *testing.T) {
+		f, svc, store := newClipboardFixture(t)
+		_, err := svc.Set(ctx, 7, ClipboardMove, []uint{2, 1, 5})
+		require.NoError(t, err)
+		taken := newTestFile(6, 7, uintPtr(3), "a.txt", false)
+		taken.Path, taken.Status = "/docs/sub", "active"
+		f.folders.files[6] = taken
+		f.folders.files[5].Status = "deleted"
+
+		_, err = svc.Paste(ctx, 7, uintPtr(3))
+		var conflictErr *ClipboardConflictError
+		require.True(t, errors.As(err, &conflictErr), "expected ClipboardConflictError, got %v", err)
+		assert.True(t, errors.Is(err, pkgErrors.ErrResourceExists))
+
+		reasons := make(map[uint]string)
+		for _, conflict := range conflictErr.Conflicts {
+			reasons[conflict.FileID] = conflict.Reason
+		}
+		assert.Equal(t, map[uint]string{
+			5: ClipboardConflictMissing,
+			1: ClipboardConflictIntoSelf,
+		}, reasons, "a.txt 位于 /docs 下，随 /docs 一起处理")
+
+		assert.Equal(t, "/docs/a.txt", f.folders.files[2].GetFullPath())
+		clipboard, err := store.Get(ctx, 7)
+		require.NoError(t, err)
+		assert.Len(t, clipboard.FileIDs, 3, "存在冲突时剪贴板不变")
+	})
+
+	t.Run("name conflict on move", func(t *testing.T) {
+		f, svc, _ := newClipboardFixture(t)
+		taken := newTestFile(6, 7, uintPtr(5), "a.txt", false)
+		taken.Path, taken.Status = "/archive", "active"
+		f.folders.files[6] = taken
+		_, err := svc.Set(ctx, 7, ClipboardMove, []uint{2, 4})
+		require.NoError(t, err)
+
+		_, err = svc.Paste(ctx, 7, uintPtr(5))
+		var conflictErr *ClipboardConflictError
+		require.True(t, errors.As(err, &conflictErr))
+		require.Len(t, conflictErr.Conflicts, 1)
+		assert.Equal(t, ClipboardConflict{FileID: 2, Name: "a.txt", Reason: ClipboardConflictNameExists, Message: "目标文件夹下已存在同名文件"}, conflictErr.Conflicts[0])
+		assert.Equal(t, "/docs/sub/b.txt", f.folders.files[4].GetFullPath())
+	})
+
+	t.Run("copy keeps clipboard", func(t *testing.T) {
+		f, svc, store := newClipboardFixture(t)
+		f.accounts.On("GetByID", mock.Anything, uint(7)).Return(&models.User{}, nil)
+		f.accounts.On("UpdateStorageUsed", mock.Anything, uint(7), int64(5)).Return(nil)
+		_, err := svc.Set(ctx, 7, ClipboardCopy, []uint{2})
+		require.NoError(t, err)
+
+		result, err := svc.Paste(ctx, 7, nil)
+		require.NoError(t, err)
+		require.Len(t, result.Files, 1)
+		assert.Equal(t, "/a.txt", result.Files[0].GetFullPath())
+		assert.Equal(t, "/docs/a.txt", f.folders.files[2].GetFullPath())
+
+		clipboard, err := store.Get(ctx, 7)
+		require.NoError(t, err)
+		require.NotNil(t, clipboard, "复制粘贴后剪贴板保留")
+		assert.Equal(t, []uint64{2}, clipboard.FileIDs)
+	})
+}
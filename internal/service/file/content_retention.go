@@ -0,0 +1,303 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/config"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	filerepo "cloudpan/internal/repository/file"
+	"cloudpan/internal/repository/models"
+	"cloudpan/internal/service/featureflag"
+)
+
+// ContentRetainer 彻底删除文件记录时按保留策略保留存储对象，由 ContentRetentionService 实现
+type ContentRetainer interface {
+	// RetainContent 登记文件的存储对象，返回false表示用户的保留时长为0，由调用方立即删除存储对象
+	RetainContent(ctx context.Context, userID uint, files []*models.File) (bool, error)
+}
+
+// ContentRetentionService 删除后存储对象保留服务接口
+//
+// 回收站项目彻底删除时文件记录立即删除并释放存储配额，存储对象按保留策略额外保留：
+// 1. 保留策略：按租户、套餐(用户资料中的tenant、plan)或默认时长计算保留期
+// 2. 查询：管理员分页列出保留的存储对象
+// 3. 恢复：保留期内管理员从存储对象重新创建文件记录，恢复到原用户的根目录并重新计入存储用量
+// 4. 到期清理：超过保留期且未恢复的存储对象由维护任务删除
+//
+// 使用示例：
+//
+//	service := NewContentRetentionService(retainedRepo, userRepo, trashRepo, store, ContentRetentionOptionsFromConfig(cfg.Storage.Trash), logger)
+//	trash := NewTrashService(fileRepo, trashRepo, userRepo, store, fileService, service, versionService, userRepo, TrashOptionsFromConfig(cfg.Storage.Trash), logger)
+//	restored, err := service.RestoreRetained(ctx, adminID, objectID)
+type ContentRetentionService interface {
+	ContentRetainer
+	ListRetained(ctx context.Context, userID uint, page, pageSize int) ([]*models.RetainedObject, int64, error)
+	RestoreRetained(ctx context.Context, adminID, id uint) (*RestoredFile, error)
+	PurgeExpired(ctx context.Context, limit int) (int64, error)
+}
+
+// ContentRetentionOptions 存储对象保留选项
+type ContentRetentionOptions struct {
+	Retention time.Duration            // 默认保留时长，0表示立即删除
+	Plans     map[string]time.Duration // 按套餐设置的保留时长
+	Tenants   map[string]time.Duration // 按租户设置的保留时长，优先于套餐
+}
+
+// ContentRetentionOptionsFromConfig 从回收站配置生成存储对象保留选项
+func ContentRetentionOptionsFromConfig(cfg config.TrashConfig) ContentRetentionOptions {
+	return ContentRetentionOptions{
+		Retention: cfg.ContentRetention,
+		Plans:     cfg.ContentRetentionPlans,
+		Tenants:   cfg.ContentRetentionTenants,
+	}
+}
+
+// RetentionAccountStore 读取用户的套餐和租户、更新存储用量，由用户仓储实现
+type RetentionAccountStore interface {
+	GetByID(ctx context.Context, id uint) (*models.User, error)
+	UpdateStorageUsed(ctx context.Context, userID uint, size int64) error
+}
+
+// RetentionNameChecker 检查目标文件夹下的同名文件，由 filerepo.TrashRepository 实现
+type RetentionNameChecker interface {
+	NameExists(ctx context.Context, userID uint, parentID *uint, name string) (bool, error)
+}
+
+// contentRetentionService 删除后存储对象保留服务实现
+type contentRetentionService struct {
+	repo     filerepo.RetainedObjectRepository
+	accounts RetentionAccountStore
+	names    RetentionNameChecker
+	store    ObjectDeleter
+	options  ContentRetentionOptions
+	logger   *zap.Logger
+	now      func() time.Time
+}
+
+// NewContentRetentionService 创建删除后存储对象保留服务
+func NewContentRetentionService(repo filerepo.RetainedObjectRepository, accounts RetentionAccountStore, names RetentionNameChecker,
+	store ObjectDeleter, options ContentRetentionOptions, logger *zap.Logger) ContentRetentionService {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &contentRetentionService{
+		repo:     repo,
+		accounts: accounts,
+		names:    names,
+		store:    store,
+		options:  options,
+		logger:   logger,
+		now:      time.Now,
+	}
+}
+
+// RetainContent 按用户的保留时长登记文件的存储对象，文件夹和没有存储对象的文件被忽略
+func (s *contentRetentionService) RetainContent(ctx context.Context, userID uint, files []*models.File) (bool, error) {
+	retention, err := s.retentionFor(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	if retention <= 0 {
+		return false, nil
+	}
+
+	now := s.now()
+	objects := make([]*models.RetainedObject, 0, len(files))
+	for _, file := range files {
+		if file.IsFolder || file.StoragePath == nil {
+			continue
+		}
+		objects = append(objects, &models.RetainedObject{
+			UserID:        file.UserID,
+			FileID:        file.ID,
+			Name:          file.Name,
+			OriginalPath:  file.GetFullPath(),
+			Size:          file.Size,
+			MimeType:      file.MimeType,
+			Hash:          file.Hash,
+			HashType:      file.HashType,
+			StorageType:   file.StorageType,
+			StoragePath:   *file.StoragePath,
+			StorageBucket: file.StorageBucket,
+			ScanStatus:    file.ScanStatus,
+			IsEncrypted:   file.IsEncrypted,
+			EncryptionKey: file.EncryptionKey,
+			PurgedAt:      now,
+			DeleteAfter:   now.Add(retention),
+		})
+	}
+	if err := s.repo.CreateBatch(ctx, objects); err != nil {
+		return false, fmt.Errorf("登记保留的存储对象失败: %w", err)
+	}
+	return true, nil
+}
+
+// ListRetained 分页列出保留的存储对象，userID为0时列出全部用户
+func (s *contentRetentionService) ListRetained(ctx context.Context, userID uint, page, pageSize int) ([]*models.RetainedObject, int64, error) {
+	objects, total, err := s.repo.List(ctx, userID, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("获取保留的存储对象失败: %w", err)
+	}
+	return objects, total, nil
+}
+
+// RestoreRetained 从保留的存储对象重新创建文件记录
+//
+// 文件恢复到原用户的根目录，同名冲突时自动重命名，并重新计入用户的存储用量
+func (s *contentRetentionService) RestoreRetained(ctx context.Context, adminID, id uint) (*RestoredFile, error) {
+	if adminID == 0 || id == 0 {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "管理员ID和保留记录ID不能为空")
+	}
+
+	object, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "保留记录不存在")
+		}
+		return nil, fmt.Errorf("获取保留记录失败: %w", err)
+	}
+	now := s.now()
+	if object.RestoredAt != nil || !now.Before(object.DeleteAfter) {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "存储对象已恢复或已过保留期")
+	}
+	if _, err := s.accounts.GetByID(ctx, object.UserID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "原用户不存在")
+		}
+		return nil, fmt.Errorf("获取用户失败: %w", err)
+	}
+
+	name, ok, err := nextAvailableName(object.Name, false, func(candidate string) (bool, error) {
+		return s.names.NameExists(ctx, object.UserID, nil, candidate)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrResourceExists, "根目录同名文件过多，请先重命名后再恢复")
+	}
+
+	file := newRestoredFile(object, name)
+	if err := s.repo.Restore(ctx, object.ID, file, adminID, now); err != nil {
+		if errors.Is(err, filerepo.ErrRetainedObjectUnavailable) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "存储对象已恢复或已过保留期")
+		}
+		return nil, fmt.Errorf("恢复文件失败: %w", err)
+	}
+	if err := s.accounts.UpdateStorageUsed(ctx, object.UserID, object.Size); err != nil {
+		// 文件已恢复，用量偏差由管理员重新统计修正
+		s.logger.Error("Failed to add storage usage of restored file",
+			zap.Uint("user_id", object.UserID),
+			zap.Int64("size", object.Size),
+			zap.Error(err))
+	}
+
+	s.logger.Warn("File restored from retained content",
+		zap.Uint("admin_id", adminID),
+		zap.Uint("retained_id", object.ID),
+		zap.Uint("user_id", object.UserID),
+		zap.Uint("file_id", file.ID))
+	return &RestoredFile{
+		FileID:    file.ID,
+		Name:      name,
+		Path:      file.GetFullPath(),
+		Relocated: true,
+		Renamed:   name != object.Name,
+	}, nil
+}
+
+// PurgeExpired 删除一批超过保留期且未恢复的存储对象及其保留记录，返回删除的数量
+//
+// 单个存储对象删除失败不影响其他对象，失败的记录在下次清理时重试
+func (s *contentRetentionService) PurgeExpired(ctx context.Context, limit int) (int64, error) {
+	objects, err := s.repo.ListExpired(ctx, s.now(), limit)
+	if err != nil {
+		return 0, fmt.Errorf("查询过期的保留记录失败: %w", err)
+	}
+
+	ids := make([]uint, 0, len(objects))
+	var failed error
+	for _, object := range objects {
+		if ctx.Err() != nil {
+			failed = ctx.Err()
+			break
+		}
+		if err := s.store.Delete(ctx, object.StoragePath); err != nil {
+			failed = err
+			s.logger.Warn("Failed to delete retained object",
+				zap.Uint("retained_id", object.ID),
+				zap.String("path", object.StoragePath),
+				zap.Error(err))
+			continue
+		}
+		ids = append(ids, object.ID)
+	}
+
+	deleted, err := s.repo.DeleteExpired(ctx, ids)
+	if err != nil {
+		return deleted, fmt.Errorf("删除保留记录失败: %w", err)
+	}
+	return deleted, failed
+}
+
+// retentionFor 计算用户的存储对象保留时长：租户 -> 套餐 -> 默认
+//
+// 未配置租户和套餐时不读取用户；用户已不存在时使用默认时长
+func (s *contentRetentionService) retentionFor(ctx context.Context, userID uint) (time.Duration, error) {
+	if len(s.options.Tenants) == 0 && len(s.options.Plans) == 0 {
+		return s.options.Retention, nil
+	}
+
+	user, err := s.accounts.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return s.options.Retention, nil
+		}
+		// 读取失败时不能按默认时长删除，彻底删除在下次清理时重试
+		return 0, fmt.Errorf("获取用户保留策略失败: %w", err)
+	}
+	subject := featureflag.SubjectFromUser(user)
+	if retention, ok := s.options.Tenants[strings.ToLower(subject.Tenant)]; ok && subject.Tenant != "" {
+		return retention, nil
+	}
+	if retention, ok := s.options.Plans[strings.ToLower(subject.Plan)]; ok {
+		return retention, nil
+	}
+	return s.options.Retention, nil
+}
+
+// newRestoredFile 根据保留记录创建恢复到根目录的文件记录
+func newRestoredFile(object *models.RetainedObject, name string) *models.File {
+	storagePath := object.StoragePath
+	file := &models.File{
+		UserID:        object.UserID,
+		Name:          name,
+		Path:          "/",
+		MimeType:      object.MimeType,
+		Size:          object.Size,
+		Hash:          object.Hash,
+		HashType:      object.HashType,
+		StorageType:   object.StorageType,
+		StoragePath:   &storagePath,
+		StorageBucket: object.StorageBucket,
+		IsEncrypted:   object.IsEncrypted,
+		EncryptionKey: object.EncryptionKey,
+		AccessLevel:   "private",
+		Status:        "active",
+		UploadStatus:  "completed",
+		ScanStatus:    object.ScanStatus,
+	}
+	if ext := strings.TrimPrefix(path.Ext(name), "."); ext != "" {
+		ext = strings.ToLower(ext)
+		file.Extension = &ext
+	}
+	return file
+}
@@ -0,0 +1,210 @@
+package file
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	basemodels "cloudpan/internal/pkg/database/models"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	filerepo "cloudpan/internal/repository/file"
+	"cloudpan/internal/repository/models"
+)
+
+// memoryRetainedObjects 内存保留记录仓储，恢复的文件写入回收站的文件仓储
+type memoryRetainedObjects struct {
+	objects map[uint]*models.RetainedObject
+	files   *memoryTrash
+	nextID  uint
+}
+
+func newMemoryRetainedObjects(files *memoryTrash) *memoryRetainedObjects {
+	return &memoryRetainedObjects{objects: make(map[uint]*models.RetainedObject), files: files}
+}
+
+func (m *memoryRetainedObjects) CreateBatch(_ context.Context, objects []*models.RetainedObject) error {
+	for _, object := range objects {
+		duplicate := false
+		for _, existing := range m.objects {
+			duplicate = duplicate || existing.FileID == object.FileID
+		}
+		if duplicate {
+			continue
+		}
+		m.nextID++
+		object.ID = m.nextID
+		m.objects[object.ID] = object
+	}
+	return nil
+}
+
+func (m *memoryRetainedObjects) GetByID(_ context.Context, id uint) (*models.RetainedObject, error) {
+	if object, ok := m.objects[id]; ok {
+		return object, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *memoryRetainedObjects) List(_ context.Context, userID uint, limit, offset int) ([]*models.RetainedObject, int64, error) {
+	var objects []*models.RetainedObject
+	for _, object := range m.objects {
+		if userID == 0 || object.UserID == userID {
+			objects = append(objects, object)
+		}
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].ID < objects[j].ID })
+	return objects, int64(len(objects)), nil
+}
+
+func (m *memoryRetainedObjects) ListExpired(_ context.Context, now time.Time, limit int) ([]*models.RetainedObject, error) {
+	var objects []*models.RetainedObject
+	for _, object := range m.objects {
+		if object.RestoredAt == nil && !object.DeleteAfter.After(now) {
+			objects = append(objects, object)
+		}
+	}
+	return objects, nil
+}
+
+func (m *memoryRetainedObjects) Restore(_ context.Context, id uint, file *models.File, restoredBy uint, restoredAt time.Time) error {
+	object := m.objects[id]
+	if object == nil || object.RestoredAt != nil || !object.DeleteAfter.After(restoredAt) {
+		return filerepo.ErrRetainedObjectUnavailable
+	}
+	object.RestoredBy, object.RestoredAt = &restoredBy, &restoredAt
+	file.ID = 100 + id
+	m.files.files[file.ID] = file
+	object.RestoredFileID = &file.ID
+	return nil
+}
+
+func (m *memoryRetainedObjects) DeleteExpired(_ context.Context, ids []uint) (int64, error) {
+	var deleted int64
+	for _, id := range ids {
+		if object, ok := m.objects[id]; ok && object.RestoredAt == nil {
+			delete(m.objects, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// newRetentionFixture 在回收站测试目录树上启用存储对象保留
+func newRetentionFixture(options ContentRetentionOptions) (*trashFixture, *contentRetentionService, *memoryRetainedObjects) {
+	f := newTrashFixture()
+	repo := newMemoryRetainedObjects(f.trash)
+	retention := NewContentRetentionService(repo, f.accounts, f.trash, f.store, options, nil).(*contentRetentionService)
+	retention.now = func() time.Time { return trashTestNow }
+	f.service.retainer = retention
+	return f, retention, repo
+}
+
+func TestContentRetention_PurgeKeepsContent(t *testing.T) {
+	ctx := context.Background()
+	f, retention, repo := newRetentionFixture(ContentRetentionOptions{Retention: 7 * 24 * time.Hour})
+	item, err := f.service.MoveToTrash(ctx, 7, 1)
+	require.NoError(t, err)
+	f.accounts.On("UpdateStorageUsed", mock.Anything, uint(7), int64(-150)).Return(nil).Once()
+
+	require.NoError(t, f.service.DeletePermanently(ctx, 7, item.ID))
+	assert.Empty(t, f.trash.files, "文件记录立即删除")
+	assert.Empty(t, f.store.deleted, "存储对象保留")
+	require.Len(t, repo.objects, 2)
+	for _, object := range repo.objects {
+		assert.Equal(t, trashTestNow.Add(7*24*time.Hour), object.DeleteAfter)
+	}
+
+	// 保留期内不删除，到期后删除存储对象和保留记录
+	removed, err := retention.PurgeExpired(ctx, 10)
+	require.NoError(t, err)
+	assert.Zero(t, removed)
+	retention.now = func() time.Time { return trashTestNow.Add(8 * 24 * time.Hour) }
+	removed, err = retention.PurgeExpired(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), removed)
+	assert.ElementsMatch(t, []string{"files/a", "files/b"}, f.store.deleted)
+	assert.Empty(t, repo.objects)
+}
+
+func TestContentRetention_NoRetentionDeletesImmediately(t *testing.T) {
+	ctx := context.Background()
+	f, _, repo := newRetentionFixture(ContentRetentionOptions{})
+	item, err := f.service.MoveToTrash(ctx, 7, 2)
+	require.NoError(t, err)
+	f.accounts.On("UpdateStorageUsed", mock.Anything, uint(7), int64(-100)).Return(nil).Once()
+
+	require.NoError(t, f.service.DeletePermanently(ctx, 7, item.ID))
+	assert.Equal(t, []string{"files/a"}, f.store.deleted)
+	assert.Empty(t, repo.objects)
+}
+
+func TestContentRetention_RetentionByTenantAndPlan(t *testing.T) {
+	ctx := context.Background()
+	options := ContentRetentionOptions{
+		Retention: time.Hour,
+		Plans:     map[string]time.Duration{"enterprise": 90 * 24 * time.Hour, "free": 0},
+		Tenants:   map[string]time.Duration{"acme": 365 * 24 * time.Hour},
+	}
+	_, retention, _ := newRetentionFixture(options)
+	accounts := new(MockStorageAccountStore)
+	retention.accounts = accounts
+
+	withProfile := func(id uint, profile basemodels.JSONMap) *models.User {
+		u := &models.User{Profile: &profile}
+		u.ID = id
+		return u
+	}
+	accounts.On("GetByID", mock.Anything, uint(1)).Return(withProfile(1, basemodels.JSONMap{"plan": "Enterprise", "tenant": "acme"}), nil)
+	accounts.On("GetByID", mock.Anything, uint(2)).Return(withProfile(2, basemodels.JSONMap{"plan": "enterprise"}), nil)
+	accounts.On("GetByID", mock.Anything, uint(3)).Return(withProfile(3, basemodels.JSONMap{"plan": "free"}), nil)
+	accounts.On("GetByID", mock.Anything, uint(4)).Return(nil, gorm.ErrRecordNotFound)
+
+	for userID, want := range map[uint]time.Duration{1: 365 * 24 * time.Hour, 2: 90 * 24 * time.Hour, 3: 0, 4: time.Hour} {
+		got, err := retention.retentionFor(ctx, userID)
+		require.NoError(t, err)
+		assert.Equal(t, want, got, "user %d", userID)
+	}
+}
+
+func TestContentRetention_RestoreRetained(t *testing.T) {
+	ctx := context.Background()
+	f, retention, repo := newRetentionFixture(ContentRetentionOptions{Retention: 24 * time.Hour})
+	item, err := f.service.MoveToTrash(ctx, 7, 2)
+	require.NoError(t, err)
+	f.accounts.On("UpdateStorageUsed", mock.Anything, uint(7), int64(-100)).Return(nil).Once()
+	require.NoError(t, f.service.DeletePermanently(ctx, 7, item.ID))
+	require.Len(t, repo.objects, 1)
+
+	// 根目录已有同名文件时自动重命名
+	existing := newTestFile(50, 7, nil, "a.txt", false)
+	existing.Path, existing.Status = "/", "active"
+	f.trash.files[existing.ID] = existing
+	f.accounts.On("GetByID", mock.Anything, uint(7)).Return(&models.User{}, nil)
+	f.accounts.On("UpdateStorageUsed", mock.Anything, uint(7), int64(100)).Return(nil).Once()
+
+	restored, err := retention.RestoreRetained(ctx, 1, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "a (1).txt", restored.Name)
+	assert.Equal(t, "/a (1).txt", restored.Path)
+	assert.True(t, restored.Relocated)
+	file := f.trash.files[restored.FileID]
+	require.NotNil(t, file)
+	assert.Equal(t, "files/a", *file.StoragePath)
+	assert.Equal(t, "active", file.Status)
+	f.accounts.AssertExpectations(t)
+
+	// 已恢复的存储对象不能再次恢复，也不会被到期清理删除
+	_, err = retention.RestoreRetained(ctx, 1, 1)
+	assert.True(t, pkgErrors.IsPermissionError(err))
+	retention.now = func() time.Time { return trashTestNow.Add(48 * time.Hour) }
+	removed, err := retention.PurgeExpired(ctx, 10)
+	require.NoError(t, err)
+	assert.Zero(t, removed)
+	assert.Empty(t, f.store.deleted)
+}
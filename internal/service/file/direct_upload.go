@@ -0,0 +1,361 @@
+package file
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	basemodels "cloudpan/internal/pkg/database/models"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/storage"
+	"cloudpan/internal/pkg/utils"
+	filerepo "cloudpan/internal/repository/file"
+	"cloudpan/internal/repository/models"
+)
+
+// 直传限制默认值
+const (
+	DefaultDirectUploadMinSize   = 100 * 1024 * 1024       // 100MB
+	DefaultDirectUploadMaxSize   = 50 * 1024 * 1024 * 1024 // 50GB
+	DefaultDirectUploadKeyPrefix = "direct"
+	// directUploadReserveGrace 凭证过期后保留预留的时间，覆盖浏览器上传结束到完成回调之间的间隔
+	directUploadReserveGrace = time.Hour
+)
+
+// DirectUploadService 浏览器直传对象存储服务接口
+//
+// 大文件由浏览器直接上传到OSS/S3，不经过应用服务器中转：
+// 1. 申请：校验文件名、大小和目标文件夹的子项数，按声明大小预留存储空间，登记上传中的文件记录，签发只能上传该对象的预签名表单
+// 2. 完成：浏览器上传成功后回调，服务端核对对象大小和SHA-256后激活文件，提交预留并累计存储用量
+//
+// 使用示例：
+//
+//	service := NewDirectUploadService(fileRepo, quotaService, presigner, folderLimiter, DirectUploadOptions{StorageType: "s3"}, logger)
+//	ticket, err := service.Initiate(ctx, userID, &DirectUploadRequest{Name: "a.mp4", Size: size, SHA256: hash})
+//	// 浏览器将文件连同 ticket.Form.Fields 提交到 ticket.Form.URL
+//	file, err := service.Complete(ctx, userID, ticket.UploadID)
+type DirectUploadService interface {
+	Initiate(ctx context.Context, userID uint, req *DirectUploadRequest) (*DirectUploadTicket, error)
+	Complete(ctx context.Context, userID uint, uploadID string) (*models.File, error)
+}
+
+// ObjectPresigner 对象存储预签名和对象核对，由 storage.S3Presigner 实现
+type ObjectPresigner interface {
+	Bucket() string
+	PresignPost(policy storage.PostPolicy) (*storage.PresignedPost, error)
+	HeadObject(ctx context.Context, key string) (*storage.ObjectInfo, error)
+}
+
+// StorageAccountStore 用户存储用量读写，由用户仓储实现
+type StorageAccountStore interface {
+	GetByID(ctx context.Context, id uint) (*models.User, error)
+	UpdateStorageUsed(ctx context.Context, userID uint, size int64) error
+}
+
+// QuotaAccountant 上传存储空间记账，由 user.StorageQuotaService 实现
+//
+// 申请上传时预留声明大小，完成时按实际大小提交，失败或过期时释放；空间不足时 Reserve 返回配额超出错误
+type QuotaAccountant interface {
+	Reserve(ctx context.Context, userID uint, uploadID string, size int64, expiresAt time.Time) error
+	Commit(ctx context.Context, userID uint, uploadID string, size int64) error
+	Release(ctx context.Context, uploadID string) error
+}
+
+// DirectUploadOptions 直传选项
+type DirectUploadOptions struct {
+	StorageType string        // 文件记录的存储类型(oss/s3/minio)
+	MinSize     int64         // 允许直传的最小文件大小
+	MaxSize     int64         // 允许直传的最大文件大小
+	URLExpiry   time.Duration // 上传凭证有效期
+	KeyPrefix   string        // 对象键前缀
+
+	// Checksums 登记和激活上传文件后使目标文件夹父链上的校验和失效，为nil时跳过
+	Checksums ChecksumInvalidator
+}
+
+// DirectUploadRequest 直传申请
+type DirectUploadRequest struct {
+	Name     string `json:"name" binding:"required"`   // 文件名
+	Size     int64  `json:"size" binding:"required"`   // 文件大小(字节)
+	SHA256   string `json:"sha256" binding:"required"` // 文件SHA-256(十六进制)
+	MimeType string `json:"mime_type"`                 // 内容类型
+	ParentID *uint  `json:"parent_id"`                 // 目标文件夹ID，为空表示根目录
+}
+
+// DirectUploadTicket 直传凭证
+type DirectUploadTicket struct {
+	UploadID  string                 `json:"upload_id"`  // 上传任务ID(即文件UUID)，完成回调时使用
+	FileID    uint                   `json:"file_id"`    // 文件ID
+	Name      string                 `json:"name"`       // 规范化后的文件名
+	Form      *storage.PresignedPost `json:"form"`       // 预签名上传表单
+	ExpiresAt time.Time              `json:"expires_at"` // 凭证过期时间
+}
+
+// directUploadService 直传服务实现
+type directUploadService struct {
+	fileRepo  filerepo.FileRepository
+	quota     QuotaAccountant
+	presigner ObjectPresigner
+	folders   FolderLimiter
+	options   DirectUploadOptions
+	logger    *zap.Logger
+	now       func() time.Time
+}
+
+// NewDirectUploadService 创建直传服务，未配置的选项使用默认值；folders 为nil时不限制目标文件夹的子项数
+func NewDirectUploadService(fileRepo filerepo.FileRepository, quota QuotaAccountant, presigner ObjectPresigner, folders FolderLimiter, options DirectUploadOptions, logger *zap.Logger) DirectUploadService {
+	if options.MinSize < 0 {
+		options.MinSize = 0
+	}
+	if options.MaxSize <= 0 {
+		options.MaxSize = DefaultDirectUploadMaxSize
+	}
+	if options.URLExpiry <= 0 {
+		options.URLExpiry = storage.DefaultPresignExpiry
+	}
+	options.KeyPrefix = strings.Trim(options.KeyPrefix, "/")
+	if options.KeyPrefix == "" {
+		options.KeyPrefix = DefaultDirectUploadKeyPrefix
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &directUploadService{
+		fileRepo:  fileRepo,
+		quota:     quota,
+		presigner: presigner,
+		folders:   folders,
+		options:   options,
+		logger:    logger,
+		now:       time.Now,
+	}
+}
+
+// Initiate 申请直传凭证并登记上传中的文件记录
+func (s *directUploadService) Initiate(ctx context.Context, userID uint, req *DirectUploadRequest) (*DirectUploadTicket, error) {
+	if userID == 0 || req == nil {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "用户ID和上传信息不能为空")
+	}
+
+	name, err := utils.NormalizeFileName(req.Name)
+	if err != nil {
+		return nil, err
+	}
+	if req.Size < s.options.MinSize {
+		return nil, pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "小于 %d 字节的文件请使用普通上传", s.options.MinSize)
+	}
+	if req.Size <= 0 || req.Size > s.options.MaxSize {
+		return nil, pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "直传文件大小必须在1到 %d 字节之间", s.options.MaxSize)
+	}
+	hash := strings.ToLower(strings.TrimSpace(req.SHA256))
+	if raw, err := hex.DecodeString(hash); err != nil || len(raw) != 32 {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrInvalidFormat, "SHA-256必须是64位十六进制字符串")
+	}
+
+	parentPath, err := s.resolveParent(ctx, userID, req.ParentID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkFolderLimits(ctx, s.folders, userID, req.ParentID, parentPath, 0); err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	uploadID := basemodels.GenerateUUID()
+	if err := s.quota.Reserve(ctx, userID, uploadID, req.Size, now.Add(s.options.URLExpiry+directUploadReserveGrace)); err != nil {
+		return nil, err
+	}
+	key := path.Join(s.options.KeyPrefix, fmt.Sprintf("%d", userID), now.UTC().Format("2006/01"), uploadID)
+	contentType := strings.TrimSpace(req.MimeType)
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	// 先签发凭证再登记记录，签名失败时不会留下无法完成的上传记录
+	form, err := s.presigner.PresignPost(storage.PostPolicy{
+		Key:         key,
+		ContentType: contentType,
+		Size:        req.Size,
+		SHA256:      hash,
+		Expires:     s.options.URLExpiry,
+	})
+	if err != nil {
+		s.release(ctx, uploadID)
+		return nil, fmt.Errorf("签发上传凭证失败: %w", err)
+	}
+
+	bucket := s.presigner.Bucket()
+	hashType := "sha256"
+	file := &models.File{
+		UUID:          uploadID,
+		UserID:        userID,
+		ParentID:      req.ParentID,
+		Name:          name,
+		Path:          parentPath,
+		MimeType:      &contentType,
+		Size:          req.Size,
+		Hash:          &hash,
+		HashType:      &hashType,
+		StorageType:   s.options.StorageType,
+		StoragePath:   &key,
+		StorageBucket: &bucket,
+		AccessLevel:   "private",
+		Status:        "uploading",
+		UploadStatus:  "pending",
+	}
+	if ext := strings.TrimPrefix(path.Ext(name), "."); ext != "" {
+		ext = strings.ToLower(ext)
+		file.Extension = &ext
+	}
+	if err := s.fileRepo.Create(ctx, file); err != nil {
+		s.release(ctx, uploadID)
+		return nil, fmt.Errorf("登记上传文件失败: %w", err)
+	}
+	s.invalidateChecksums(ctx, file.ParentID)
+
+	s.logger.Info("Direct upload initiated",
+		zap.Uint("user_id", userID),
+		zap.Uint("file_id", file.ID),
+		zap.String("upload_id", file.UUID),
+		zap.Int64("size", req.Size))
+
+	return &DirectUploadTicket{
+		UploadID:  file.UUID,
+		FileID:    file.ID,
+		Name:      file.Name,
+		Form:      form,
+		ExpiresAt: form.ExpiresAt,
+	}, nil
+}
+
+// Complete 核对已上传的对象并激活文件
+//
+// 重复回调是安全的：文件已激活时直接返回，存储用量只累计一次。
+// 对象尚未出现时返回验证错误，客户端可稍后重试；大小或校验和不一致时上传记录标记为失败
+func (s *directUploadService) Complete(ctx context.Context, userID uint, uploadID string) (*models.File, error) {
+	if userID == 0 || uploadID == "" {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "用户ID和上传任务ID不能为空")
+	}
+
+	file, err := s.fileRepo.GetByUUID(ctx, uploadID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "上传任务不存在")
+		}
+		return nil, fmt.Errorf("获取上传文件失败: %w", err)
+	}
+	if file.UserID != userID {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, "无权操作该上传任务")
+	}
+	if file.IsActive() {
+		return file, nil
+	}
+	if file.Status != "uploading" || file.StoragePath == nil {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "上传任务已失效")
+	}
+
+	info, err := s.presigner.HeadObject(ctx, *file.StoragePath)
+	if err != nil {
+		if storage.IsNotFound(err) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrValidationFailed, "存储中尚未找到上传的文件")
+		}
+		return nil, fmt.Errorf("核对上传文件失败: %w", err)
+	}
+
+	if reason := s.verifyObject(file, info); reason != "" {
+		if err := s.fileRepo.FailUpload(ctx, file.ID); err != nil {
+			s.logger.Warn("Failed to mark direct upload as failed", zap.Uint("file_id", file.ID), zap.Error(err))
+		}
+		s.release(ctx, file.UUID)
+		s.logger.Warn("Direct upload verification failed",
+			zap.Uint("user_id", userID),
+			zap.Uint("file_id", file.ID),
+			zap.String("reason", reason))
+		return nil, pkgErrors.WrapError(pkgErrors.ErrValidationFailed, reason)
+	}
+
+	completed, err := s.fileRepo.CompleteUpload(ctx, file.ID, info.Size)
+	if err != nil {
+		return nil, fmt.Errorf("激活上传文件失败: %w", err)
+	}
+	if completed {
+		// 只有完成状态切换的那次回调提交预留，避免并发回调重复计算
+		if err := s.quota.Commit(ctx, userID, file.UUID, info.Size); err != nil {
+			s.logger.Error("Failed to update storage usage after direct upload",
+				zap.Uint("user_id", userID),
+				zap.Uint("file_id", file.ID),
+				zap.Error(err))
+		}
+		s.invalidateChecksums(ctx, file.ParentID)
+		s.logger.Info("Direct upload completed",
+			zap.Uint("user_id", userID),
+			zap.Uint("file_id", file.ID),
+			zap.Int64("size", info.Size))
+	}
+
+	file.Size = info.Size
+	file.Status = "active"
+	file.UploadStatus = "completed"
+	return file, nil
+}
+
+// invalidateChecksums 使目标文件夹及其父链上的校验和失效，上传到根目录时无需处理，失败只记录日志
+func (s *directUploadService) invalidateChecksums(ctx context.Context, parentID *uint) {
+	if s.options.Checksums == nil || parentID == nil {
+		return
+	}
+	if err := s.options.Checksums.InvalidateChecksums(ctx, *parentID); err != nil {
+		s.logger.Warn("Failed to invalidate folder checksums",
+			zap.Uint("folder_id", *parentID),
+			zap.Error(err))
+	}
+}
+
+// release 释放上传的空间预留，失败时留给过期清理
+func (s *directUploadService) release(ctx context.Context, uploadID string) {
+	if err := s.quota.Release(ctx, uploadID); err != nil {
+		s.logger.Warn("Failed to release storage reservation", zap.String("upload_id", uploadID), zap.Error(err))
+	}
+}
+
+// verifyObject 核对对象大小和SHA-256，返回不一致的原因
+//
+// 存储服务未返回校验和时只核对大小，内容已由上传策略中的校验和约束保证
+func (s *directUploadService) verifyObject(file *models.File, info *storage.ObjectInfo) string {
+	if info.Size != file.Size {
+		return fmt.Sprintf("上传文件大小不一致：期望 %d 字节，实际 %d 字节", file.Size, info.Size)
+	}
+	if info.SHA256 != "" && file.Hash != nil && !strings.EqualFold(info.SHA256, *file.Hash) {
+		return "上传文件SHA-256校验失败"
+	}
+	return ""
+}
+
+// resolveParent 校验目标文件夹并返回新文件的路径
+func (s *directUploadService) resolveParent(ctx context.Context, userID uint, parentID *uint) (string, error) {
+	if parentID == nil {
+		return "/", nil
+	}
+
+	parent, err := s.fileRepo.GetByID(ctx, *parentID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "目标文件夹不存在")
+		}
+		return "", fmt.Errorf("获取目标文件夹失败: %w", err)
+	}
+	if parent.UserID != userID {
+		return "", pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, "无权访问目标文件夹")
+	}
+	if !parent.IsFolder || !parent.IsActive() {
+		return "", pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "目标必须是有效的文件夹")
+	}
+	return parent.GetFullPath(), nil
+}
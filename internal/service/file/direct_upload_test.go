@@ -0,0 +1,322 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/storage"
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+)
+
+// MockStorageAccountStore 模拟用户存储用量仓储
+type MockStorageAccountStore struct {
+	mock.Mock
+}
+
+func (m *MockStorageAccountStore) GetByID(ctx context.Context, id uint) (*models.User, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockStorageAccountStore) UpdateStorageUsed(ctx context.Context, userID uint, size int64) error {
+	return m.Called(ctx, userID, size).Error(0)
+}
+
+// MockQuotaAccountant 模拟上传存储空间记账
+type MockQuotaAccountant struct {
+	mock.Mock
+}
+
+func (m *MockQuotaAccountant) Reserve(ctx context.Context, userID uint, uploadID string, size int64, expiresAt time.Time) error {
+	return m.Called(ctx, userID, uploadID, size, expiresAt).Error(0)
+}
+
+func (m *MockQuotaAccountant) Commit(ctx context.Context, userID uint, uploadID string, size int64) error {
+	return m.Called(ctx, userID, uploadID, size).Error(0)
+}
+
+func (m *MockQuotaAccountant) Release(ctx context.Context, uploadID string) error {
+	return m.Called(ctx, uploadID).Error(0)
+}
+
+// MockObjectPresigner 模拟对象存储预签名器
+type MockObjectPresigner struct {
+	mock.Mock
+}
+
+func (m *MockObjectPresigner) Bucket() string {
+	return "uploads"
+}
+
+func (m *MockObjectPresigner) PresignPost(policy storage.PostPolicy) (*storage.PresignedPost, error) {
+	args := m.Called(policy)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*storage.PresignedPost), args.Error(1)
+}
+
+func (m *MockObjectPresigner) HeadObject(ctx context.Context, key string) (*storage.ObjectInfo, error) {
+	args := m.Called(ctx, key)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*storage.ObjectInfo), args.Error(1)
+}
+
+var testUploadHash = strings.Repeat("ab", 32)
+
+// recordingChecksums 记录校验和失效的文件夹ID
+type recordingChecksums struct {
+	folderIDs []uint
+}
+
+func (r *recordingChecksums) InvalidateChecksums(_ context.Context, fileID uint) error {
+	r.folderIDs = append(r.folderIDs, fileID)
+	return nil
+}
+
+func newTestDirectUploadService(repo *MockFileRepository, quota *MockQuotaAccountant, presigner *MockObjectPresigner) *directUploadService {
+	svc := NewDirectUploadService(repo, quota, presigner, nil, DirectUploadOptions{
+		StorageType: "s3",
+		MinSize:     100,
+		MaxSize:     1000,
+		URLExpiry:   10 * time.Minute,
+	}, zap.NewNop()).(*directUploadService)
+	svc.now = func() time.Time { return time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC) }
+	return svc
+}
+
+func newUploadingFile(size int64) *models.File {
+	key := "direct/7/2024/03/up-1"
+	hash := testUploadHash
+	f := &models.File{UUID: "up-1", UserID: 7, Name: "movie.mp4", Size: size, Hash: &hash, StoragePath: &key, Status: "uploading"}
+	f.ID = 42
+	return f
+}
+
+func TestDirectUploadService_Initiate(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		repo := new(MockFileRepository)
+		quota := new(MockQuotaAccountant)
+		presigner := new(MockObjectPresigner)
+		svc := newTestDirectUploadService(repo, quota, presigner)
+
+		parent := newTestFile(3, 7, nil, "videos", true)
+		parent.Path = "/"
+		parent.Status = "active"
+		repo.On("GetByID", ctx, uint(3)).Return(parent, nil)
+		// 预留覆盖凭证有效期和完成回调的宽限时间
+		reserveUntil := svc.now().Add(10*time.Minute + directUploadReserveGrace)
+		quota.On("Reserve", ctx, uint(7), mock.AnythingOfType("string"), int64(500), reserveUntil).Return(nil)
+		presigner.On("PresignPost", mock.MatchedBy(func(p storage.PostPolicy) bool {
+			return strings.HasPrefix(p.Key, "direct/7/2024/03/") && p.Size == 500 && p.SHA256 == testUploadHash &&
+				p.ContentType == "video/mp4" && p.Expires == 10*time.Minute
+		})).Return(&storage.PresignedPost{URL: "https://uploads.s3.example.com/"}, nil)
+		repo.On("Create", ctx, mock.MatchedBy(func(f *models.File) bool {
+			return f.Name == "movie.mp4" && f.Path == "/videos" && f.Status == "uploading" &&
+				f.UploadStatus == "pending" && f.StorageType == "s3" && *f.HashType == "sha256" &&
+				*f.Extension == "mp4" && strings.HasSuffix(*f.StoragePath, f.UUID)
+		})).Return(nil)
+
+		ticket, err := svc.Initiate(ctx, 7, &DirectUploadRequest{
+			Name: " movie.mp4 ", Size: 500, SHA256: strings.ToUpper(testUploadHash), MimeType: "video/mp4", ParentID: uintPtr(3),
+		})
+		require.NoError(t, err)
+		assert.NotEmpty(t, ticket.UploadID)
+		assert.Equal(t, "movie.mp4", ticket.Name)
+		assert.Equal(t, "https://uploads.s3.example.com/", ticket.Form.URL)
+		assert.Equal(t, ticket.UploadID, quota.Calls[0].Arguments.String(2))
+		repo.AssertExpectations(t)
+		presigner.AssertExpectations(t)
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		svc := newTestDirectUploadService(new(MockFileRepository), new(MockQuotaAccountant), new(MockObjectPresigner))
+
+		_, err := svc.Initiate(ctx, 7, &DirectUploadRequest{Name: "a.bin", Size: 50, SHA256: testUploadHash})
+		assert.True(t, pkgErrors.IsValidationError(err), "小文件应使用普通上传")
+
+		_, err = svc.Initiate(ctx, 7, &DirectUploadRequest{Name: "a.bin", Size: 5000, SHA256: testUploadHash})
+		assert.True(t, pkgErrors.IsValidationError(err), "超过最大直传大小")
+
+		_, err = svc.Initiate(ctx, 7, &DirectUploadRequest{Name: "a.bin", Size: 500, SHA256: "abc"})
+		assert.True(t, pkgErrors.IsValidationError(err), "非法SHA-256")
+
+		_, err = svc.Initiate(ctx, 7, &DirectUploadRequest{Name: "../a.bin", Size: 500, SHA256: testUploadHash})
+		var nameErr *utils.FileNameError
+		assert.True(t, errors.As(err, &nameErr))
+	})
+
+	t.Run("quota exceeded", func(t *testing.T) {
+		quota := new(MockQuotaAccountant)
+		presigner := new(MockObjectPresigner)
+		svc := newTestDirectUploadService(new(MockFileRepository), quota, presigner)
+		quota.On("Reserve", ctx, uint(7), mock.Anything, int64(500), mock.Anything).
+			Return(pkgErrors.WrapError(pkgErrors.ErrQuotaExceeded, "存储空间不足"))
+
+		_, err := svc.Initiate(ctx, 7, &DirectUploadRequest{Name: "a.bin", Size: 500, SHA256: testUploadHash})
+		assert.ErrorIs(t, err, pkgErrors.ErrQuotaExceeded)
+		presigner.AssertNotCalled(t, "PresignPost", mock.Anything)
+	})
+
+	t.Run("create failure releases reservation", func(t *testing.T) {
+		repo := new(MockFileRepository)
+		quota := new(MockQuotaAccountant)
+		presigner := new(MockObjectPresigner)
+		svc := newTestDirectUploadService(repo, quota, presigner)
+		quota.On("Reserve", ctx, uint(7), mock.Anything, int64(500), mock.Anything).Return(nil)
+		presigner.On("PresignPost", mock.Anything).Return(&storage.PresignedPost{}, nil)
+		repo.On("Create", ctx, mock.Anything).Return(errors.New("db down"))
+		quota.On("Release", ctx, mock.Anything).Return(nil)
+
+		_, err := svc.Initiate(ctx, 7, &DirectUploadRequest{Name: "a.bin", Size: 500, SHA256: testUploadHash})
+		require.Error(t, err)
+		quota.AssertExpectations(t)
+		assert.Equal(t, quota.Calls[0].Arguments.String(2), quota.Calls[1].Arguments.String(1), "释放申请时预留的上传")
+	})
+
+	t.Run("foreign parent", func(t *testing.T) {
+		repo := new(MockFileRepository)
+		svc := newTestDirectUploadService(repo, new(MockQuotaAccountant), new(MockObjectPresigner))
+		repo.On("GetByID", ctx, uint(3)).Return(newTestFile(3, 8, nil, "other", true), nil)
+
+		_, err := svc.Initiate(ctx, 7, &DirectUploadRequest{Name: "a.bin", Size: 500, SHA256: testUploadHash, ParentID: uintPtr(3)})
+		assert.True(t, pkgErrors.IsPermissionError(err))
+	})
+}
+
+func TestDirectUploadService_Complete(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		repo := new(MockFileRepository)
+		quota := new(MockQuotaAccountant)
+		presigner := new(MockObjectPresigner)
+		svc := newTestDirectUploadService(repo, quota, presigner)
+
+		repo.On("GetByUUID", ctx, "up-1").Return(newUploadingFile(500), nil)
+		presigner.On("HeadObject", ctx, "direct/7/2024/03/up-1").Return(&storage.ObjectInfo{Size: 500, SHA256: testUploadHash}, nil)
+		repo.On("CompleteUpload", ctx, uint(42), int64(500)).Return(true, nil)
+		quota.On("Commit", ctx, uint(7), "up-1", int64(500)).Return(nil)
+
+		file, err := svc.Complete(ctx, 7, "up-1")
+		require.NoError(t, err)
+		assert.True(t, file.IsActive())
+		quota.AssertExpectations(t)
+	})
+
+	t.Run("invalidates folder checksums", func(t *testing.T) {
+		repo := new(MockFileRepository)
+		quota := new(MockQuotaAccountant)
+		presigner := new(MockObjectPresigner)
+		svc := newTestDirectUploadService(repo, quota, presigner)
+		checksums := &recordingChecksums{}
+		svc.options.Checksums = checksums
+
+		parent := newTestFile(3, 7, nil, "videos", true)
+		parent.Status = "active"
+		repo.On("GetByID", ctx, uint(3)).Return(parent, nil)
+		quota.On("Reserve", ctx, uint(7), mock.AnythingOfType("string"), int64(500), mock.Anything).Return(nil)
+		presigner.On("PresignPost", mock.Anything).Return(&storage.PresignedPost{}, nil)
+		repo.On("Create", ctx, mock.AnythingOfType("*models.File")).Return(nil)
+
+		_, err := svc.Initiate(ctx, 7, &DirectUploadRequest{Name: "movie.mp4", Size: 500, SHA256: testUploadHash, ParentID: uintPtr(3)})
+		require.NoError(t, err)
+		assert.Equal(t, []uint{3}, checksums.folderIDs, "登记上传记录后目标文件夹的子项已变化")
+
+		uploading := newUploadingFile(500)
+		uploading.ParentID = uintPtr(3)
+		repo.On("GetByUUID", ctx, "up-1").Return(uploading, nil)
+		presigner.On("HeadObject", ctx, mock.Anything).Return(&storage.ObjectInfo{Size: 500, SHA256: testUploadHash}, nil)
+		repo.On("CompleteUpload", ctx, uint(42), int64(500)).Return(true, nil)
+		quota.On("Commit", ctx, uint(7), "up-1", int64(500)).Return(nil)
+
+		_, err = svc.Complete(ctx, 7, "up-1")
+		require.NoError(t, err)
+		assert.Equal(t, []uint{3, 3}, checksums.folderIDs)
+	})
+
+	t.Run("concurrent completion counts usage once", func(t *testing.T) {
+		repo := new(MockFileRepository)
+		quota := new(MockQuotaAccountant)
+		presigner := new(MockObjectPresigner)
+		svc := newTestDirectUploadService(repo, quota, presigner)
+
+		repo.On("GetByUUID", ctx, "up-1").Return(newUploadingFile(500), nil)
+		presigner.On("HeadObject", ctx, mock.Anything).Return(&storage.ObjectInfo{Size: 500}, nil)
+		repo.On("CompleteUpload", ctx, uint(42), int64(500)).Return(false, nil)
+
+		_, err := svc.Complete(ctx, 7, "up-1")
+		require.NoError(t, err)
+		quota.AssertNotCalled(t, "Commit", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("object not uploaded yet", func(t *testing.T) {
+		repo := new(MockFileRepository)
+		presigner := new(MockObjectPresigner)
+		svc := newTestDirectUploadService(repo, new(MockQuotaAccountant), presigner)
+
+		repo.On("GetByUUID", ctx, "up-1").Return(newUploadingFile(500), nil)
+		presigner.On("HeadObject", ctx, mock.Anything).Return(nil, storage.ErrObjectNotFound)
+
+		_, err := svc.Complete(ctx, 7, "up-1")
+		assert.True(t, pkgErrors.IsValidationError(err))
+		repo.AssertNotCalled(t, "FailUpload", mock.Anything, mock.Anything)
+	})
+
+	t.Run("checksum mismatch fails upload", func(t *testing.T) {
+		repo := new(MockFileRepository)
+		presigner := new(MockObjectPresigner)
+		quota := new(MockQuotaAccountant)
+		svc := newTestDirectUploadService(repo, quota, presigner)
+
+		repo.On("GetByUUID", ctx, "up-1").Return(newUploadingFile(500), nil)
+		presigner.On("HeadObject", ctx, mock.Anything).Return(&storage.ObjectInfo{Size: 500, SHA256: strings.Repeat("cd", 32)}, nil)
+		repo.On("FailUpload", ctx, uint(42)).Return(nil)
+		quota.On("Release", ctx, "up-1").Return(nil)
+
+		_, err := svc.Complete(ctx, 7, "up-1")
+		assert.True(t, pkgErrors.IsValidationError(err))
+		repo.AssertExpectations(t)
+		quota.AssertExpectations(t)
+	})
+
+	t.Run("other user", func(t *testing.T) {
+		repo := new(MockFileRepository)
+		svc := newTestDirectUploadService(repo, new(MockQuotaAccountant), new(MockObjectPresigner))
+		repo.On("GetByUUID", ctx, "up-1").Return(newUploadingFile(500), nil)
+
+		_, err := svc.Complete(ctx, 8, "up-1")
+		assert.True(t, pkgErrors.IsPermissionError(err))
+	})
+
+	t.Run("already active", func(t *testing.T) {
+		repo := new(MockFileRepository)
+		presigner := new(MockObjectPresigner)
+		svc := newTestDirectUploadService(repo, new(MockQuotaAccountant), presigner)
+		active := newUploadingFile(500)
+		active.Status = "active"
+		repo.On("GetByUUID", ctx, "up-1").Return(active, nil)
+
+		file, err := svc.Complete(ctx, 7, "up-1")
+		require.NoError(t, err)
+		assert.Equal(t, uint(42), file.ID)
+		presigner.AssertNotCalled(t, "HeadObject", mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,143 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/storage"
+	filerepo "cloudpan/internal/repository/file"
+	"cloudpan/internal/repository/models"
+)
+
+// DownloadService 文件下载服务接口
+//
+// 校验访问权限后返回可随机定位的文件内容，由处理器按HTTP Range请求分段传输：
+// 文件所有者可下载自己的文件，访问级别为public的文件对所有登录用户开放，
+// private和shared文件只有所有者可以下载(shared文件的访问者通过分享链接下载)
+//
+// 使用示例：
+//
+//	service := NewDownloadService(fileRepo, store, logger)
+//	download, err := service.Open(ctx, userID, fileID)
+//	defer download.Content.Close()
+//	http.ServeContent(w, r, download.Name, download.ModTime, download.Content)
+//	service.RecordDownload(ctx, download)
+type DownloadService interface {
+	Open(ctx context.Context, userID, fileID uint) (*FileDownload, error)
+	RecordDownload(ctx context.Context, download *FileDownload)
+}
+
+// FileDownload 文件下载内容
+type FileDownload struct {
+	FileID   uint              // 文件ID
+	Name     string            // 文件名
+	MimeType string            // 内容类型
+	Size     int64             // 文件大小
+	ModTime  time.Time         // 修改时间，用于Last-Modified和If-Range
+	ETag     string            // 实体标签(带引号)，文件没有哈希时为空
+	Content  io.ReadSeekCloser // 文件内容，调用方负责关闭
+}
+
+// downloadService 文件下载服务实现
+type downloadService struct {
+	fileRepo filerepo.FileRepository
+	store    storage.Storage
+	logger   *zap.Logger
+	now      func() time.Time
+}
+
+// NewDownloadService 创建文件下载服务
+func NewDownloadService(fileRepo filerepo.FileRepository, store storage.Storage, logger *zap.Logger) DownloadService {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &downloadService{
+		fileRepo: fileRepo,
+		store:    store,
+		logger:   logger,
+		now:      time.Now,
+	}
+}
+
+// Open 校验下载权限并打开文件内容
+//
+// 打开前先查询存储对象，对象缺失时在发送响应头之前返回错误；
+// 文件大小以存储对象为准
+func (s *downloadService) Open(ctx context.Context, userID, fileID uint) (*FileDownload, error) {
+	if userID == 0 || fileID == 0 {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "用户ID和文件ID不能为空")
+	}
+
+	file, err := s.fileRepo.GetByID(ctx, fileID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "文件不存在")
+		}
+		return nil, fmt.Errorf("获取文件失败: %w", err)
+	}
+	if !file.IsActive() {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "文件不存在")
+	}
+	if file.UserID != userID && file.AccessLevel != models.AccessLevelPublic {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, "无权下载该文件")
+	}
+	if file.IsFolder {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "文件夹请使用导出下载")
+	}
+	if file.StoragePath == nil {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "文件内容不存在")
+	}
+	switch file.ArchiveState(s.now()) {
+	case models.ArchiveStateArchived, models.ArchiveStateRestoring:
+		return nil, pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "文件已归档，请先恢复后再下载")
+	}
+	if file.StorageType != s.store.Type() {
+		return nil, pkgErrors.WrapErrorf(pkgErrors.ErrOperationNotAllowed, "文件所在的存储(%s)当前不可用", file.StorageType)
+	}
+
+	info, err := s.store.Stat(ctx, *file.StoragePath)
+	if err != nil {
+		if storage.IsNotFound(err) {
+			s.logger.Error("File content missing from storage",
+				zap.Uint("file_id", file.ID),
+				zap.String("path", *file.StoragePath))
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "文件内容不存在")
+		}
+		return nil, fmt.Errorf("读取文件信息失败: %w", err)
+	}
+
+	download := &FileDownload{
+		FileID:   file.ID,
+		Name:     file.Name,
+		MimeType: "application/octet-stream",
+		Size:     info.Size,
+		ModTime:  file.UpdatedAt,
+		Content:  storage.OpenSeekable(ctx, s.store, *file.StoragePath, info.Size),
+	}
+	if file.MimeType != nil && *file.MimeType != "" {
+		download.MimeType = *file.MimeType
+	}
+	if file.Hash != nil && *file.Hash != "" {
+		download.ETag = `"` + *file.Hash + `"`
+	}
+	return download, nil
+}
+
+// RecordDownload 记录一次下载，失败只记录日志
+func (s *downloadService) RecordDownload(ctx context.Context, download *FileDownload) {
+	if download == nil {
+		return
+	}
+	if err := s.fileRepo.RecordDownload(ctx, download.FileID, s.now()); err != nil {
+		s.logger.Warn("Failed to record file download",
+			zap.Uint("file_id", download.FileID),
+			zap.Error(err))
+	}
+}
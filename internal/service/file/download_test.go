@@ -0,0 +1,141 @@
+package file
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/storage"
+	"cloudpan/internal/repository/models"
+)
+
+func newDownloadFixture(t *testing.T) (*downloadService, *memoryFileRepository, *models.File) {
+	t.Helper()
+	store, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, store.Put(context.Background(), "files/5", strings.NewReader("hello world"), 11))
+
+	storagePath := "files/5"
+	hash := "5eb63bbbe01eeed093cb22bb8f5acdc3"
+	mimeType := "text/plain"
+	file := newTestFile(5, 7, nil, "hello.txt", false)
+	file.StoragePath = &storagePath
+	file.StorageType = storage.StorageTypeLocal
+	file.Hash = &hash
+	file.MimeType = &mimeType
+	file.Size = 11
+	file.Status = "active"
+
+	repo := &memoryFileRepository{files: map[uint]*models.File{5: file}}
+	service := NewDownloadService(repo, store, zap.NewNop()).(*downloadService)
+	return service, repo, file
+}
+
+func TestDownloadService_Open(t *testing.T) {
+	ctx := context.Background()
+	service, _, _ := newDownloadFixture(t)
+
+	download, err := service.Open(ctx, 7, 5)
+	require.NoError(t, err)
+	defer download.Content.Close()
+
+	assert.Equal(t, "hello.txt", download.Name)
+	assert.Equal(t, "text/plain", download.MimeType)
+	assert.Equal(t, int64(11), download.Size)
+	assert.Equal(t, `"5eb63bbbe01eeed093cb22bb8f5acdc3"`, download.ETag)
+
+	_, err = download.Content.Seek(6, io.SeekStart)
+	require.NoError(t, err)
+	rest, err := io.ReadAll(download.Content)
+	require.NoError(t, err)
+	assert.Equal(t, "world", string(rest))
+}
+
+func TestDownloadService_AccessLevel(t *testing.T) {
+	ctx := context.Background()
+
+	for _, tt := range []struct {
+		level   string
+		allowed bool
+	}{
+		{models.AccessLevelPrivate, false},
+		{models.AccessLevelShared, false},
+		{models.AccessLevelPublic, true},
+	} {
+		t.Run(tt.level, func(t *testing.T) {
+			service, _, file := newDownloadFixture(t)
+			file.AccessLevel = tt.level
+
+			download, err := service.Open(ctx, 8, 5)
+			if !tt.allowed {
+				assert.True(t, pkgErrors.IsPermissionError(err))
+				return
+			}
+			require.NoError(t, err)
+			download.Content.Close()
+		})
+	}
+}
+
+func TestDownloadService_Refusals(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("missing file", func(t *testing.T) {
+		service, _, _ := newDownloadFixture(t)
+		_, err := service.Open(ctx, 7, 99)
+		assert.True(t, pkgErrors.IsNotFoundError(err))
+	})
+
+	t.Run("deleted file", func(t *testing.T) {
+		service, _, file := newDownloadFixture(t)
+		file.Status = "deleted"
+		_, err := service.Open(ctx, 7, 5)
+		assert.True(t, pkgErrors.IsNotFoundError(err))
+	})
+
+	t.Run("archived file", func(t *testing.T) {
+		service, _, file := newDownloadFixture(t)
+		archivedAt := time.Now().Add(-time.Hour)
+		file.ArchivedAt = &archivedAt
+		_, err := service.Open(ctx, 7, 5)
+		assert.ErrorIs(t, err, pkgErrors.ErrOperationNotAllowed)
+	})
+
+	t.Run("content missing", func(t *testing.T) {
+		service, _, file := newDownloadFixture(t)
+		missing := "files/missing"
+		file.StoragePath = &missing
+		_, err := service.Open(ctx, 7, 5)
+		assert.True(t, pkgErrors.IsNotFoundError(err))
+	})
+
+	t.Run("other storage backend", func(t *testing.T) {
+		service, _, file := newDownloadFixture(t)
+		file.StorageType = storage.StorageTypeS3
+		_, err := service.Open(ctx, 7, 5)
+		assert.ErrorIs(t, err, pkgErrors.ErrOperationNotAllowed)
+	})
+}
+
+func TestDownloadService_RecordDownload(t *testing.T) {
+	ctx := context.Background()
+	service, _, file := newDownloadFixture(t)
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+
+	download, err := service.Open(ctx, 7, 5)
+	require.NoError(t, err)
+	download.Content.Close()
+
+	service.RecordDownload(ctx, download)
+	assert.Equal(t, int64(1), file.DownloadCount)
+	require.NotNil(t, file.LastAccessedAt)
+	assert.True(t, file.LastAccessedAt.Equal(now))
+}
@@ -0,0 +1,485 @@
+package file
+
+import (
+	"archive/zip"
+	"compress/flate"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/storage"
+	"cloudpan/internal/pkg/utils"
+	filerepo "cloudpan/internal/repository/file"
+	"cloudpan/internal/repository/models"
+)
+
+// 导出限制默认值
+const (
+	DefaultExportConcurrentPerUser = 2
+	DefaultExportConcurrent        = 20
+	DefaultExportMaxFiles          = 50000
+	DefaultExportMaxTotalSize      = 10 * 1024 * 1024 * 1024 // 10GB
+)
+
+// ExportLimiter 文件夹导出并发限制器
+//
+// 导出是长时间占用连接和存储读取的操作，按用户和全局两个维度限制同时进行的数量
+type ExportLimiter struct {
+	mu      sync.Mutex
+	perUser int
+	total   int
+	active  map[uint]int
+	running int
+}
+
+// NewExportLimiter 创建导出并发限制器，参数小于等于0时使用默认值
+func NewExportLimiter(perUser, total int) *ExportLimiter {
+	if perUser <= 0 {
+		perUser = DefaultExportConcurrentPerUser
+	}
+	if total <= 0 {
+		total = DefaultExportConcurrent
+	}
+	return &ExportLimiter{
+		perUser: perUser,
+		total:   total,
+		active:  make(map[uint]int),
+	}
+}
+
+// Acquire 占用一个导出名额，返回的release函数可重复调用
+func (l *ExportLimiter) Acquire(userID uint) (func(), error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.active[userID] >= l.perUser {
+		return nil, pkgErrors.WrapErrorf(pkgErrors.ErrTooManyRequests, "同时进行的导出不能超过 %d 个", l.perUser)
+	}
+	if l.running >= l.total {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrTooManyRequests, "导出任务繁忙，请稍后重试")
+	}
+	l.active[userID]++
+	l.running++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			l.running--
+			if l.active[userID]--; l.active[userID] <= 0 {
+				delete(l.active, userID)
+			}
+		})
+	}, nil
+}
+
+// Active 返回用户正在进行的导出数量
+func (l *ExportLimiter) Active(userID uint) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.active[userID]
+}
+
+// 导出超限的类型
+const (
+	ExportLimitFiles = "files" // 文件数
+	ExportLimitSize  = "size"  // 总大小
+)
+
+// ExportLimitError 导出内容超过文件数或总大小上限
+type ExportLimitError struct {
+	Limit  string `json:"limit"`  // 超出的限制(ExportLimitFiles/ExportLimitSize)
+	Max    int64  `json:"max"`    // 上限
+	Actual int64  `json:"actual"` // 超出时已统计到的文件数或字节数，实际内容可能更多
+}
+
+// Error 返回错误信息
+func (e *ExportLimitError) Error() string {
+	if e.Limit == ExportLimitFiles {
+		return fmt.Sprintf("文件夹包含的文件超过打包下载上限 %d 个(至少 %d 个)，请分别下载子文件夹", e.Max, e.Actual)
+	}
+	return fmt.Sprintf("文件夹大小超过打包下载上限 %s(至少 %s)，请分别下载子文件夹", utils.FormatBytes(e.Max), utils.FormatBytes(e.Actual))
+}
+
+// Unwrap 归类为超出配额
+func (e *ExportLimitError) Unwrap() error {
+	return pkgErrors.ErrQuotaExceeded
+}
+
+// ExportAuthorizer 文件权限检查，由 acl.PermissionService 实现
+type ExportAuthorizer interface {
+	Authorize(ctx context.Context, userID, fileID uint, permission string) error
+}
+
+// ExportOptions 导出限制选项
+type ExportOptions struct {
+	MaxFiles     int   // 单次导出最大文件数
+	MaxTotalSize int64 // 单次导出最大总大小
+}
+
+// ExportStats 导出内容统计
+type ExportStats struct {
+	Files     int   `json:"files"`      // 文件数
+	Folders   int   `json:"folders"`    // 文件夹数(含根文件夹)
+	TotalSize int64 `json:"total_size"` // 文件总大小
+	Skipped   int   `json:"skipped"`    // 无权访问或内容不可读取而跳过的条目数
+}
+
+// FolderExporter 文件夹ZIP导出器
+//
+// 导出以流式方式写出：逐个文件夹列出子项、逐个文件从存储读取，
+// 复制缓冲区和压缩器均来自对象池，内存占用与文件夹大小无关，不使用临时文件
+//
+// 每个条目单独检查权限：用户自己的条目和与上级文件夹同一所有者的条目(继承上级的授权)直接打包，
+// 所有者不同的条目需要用户对其有read权限；无权访问、上传未完成或已归档的条目被跳过
+//
+// 使用示例：
+//
+//	exporter := NewFolderExporter(fileRepo, store, NewExportLimiter(2, 20), ExportOptions{}, logger)
+//	export, err := exporter.Begin(ctx, userID, folderID)
+//	if err != nil {
+//		return err
+//	}
+//	defer export.Close()
+//	stats, err := export.WriteZip(ctx, w)
+type FolderExporter struct {
+	fileRepo   filerepo.FileRepository
+	store      storage.Storage
+	limiter    *ExportLimiter
+	authorizer ExportAuthorizer
+	options    ExportOptions
+	logger     *zap.Logger
+	now        func() time.Time
+}
+
+// NewFolderExporter 创建文件夹导出器
+func NewFolderExporter(fileRepo filerepo.FileRepository, store storage.Storage, limiter *ExportLimiter, options ExportOptions, logger *zap.Logger) *FolderExporter {
+	if limiter == nil {
+		limiter = NewExportLimiter(0, 0)
+	}
+	if options.MaxFiles <= 0 {
+		options.MaxFiles = DefaultExportMaxFiles
+	}
+	if options.MaxTotalSize <= 0 {
+		options.MaxTotalSize = DefaultExportMaxTotalSize
+	}
+	return &FolderExporter{
+		fileRepo: fileRepo,
+		store:    store,
+		limiter:  limiter,
+		options:  options,
+		logger:   logger,
+		now:      time.Now,
+	}
+}
+
+// SetAuthorizer 设置文件权限检查，使获得授权的用户可以导出他人的文件夹；
+// 未设置时只能导出自己的文件夹，其中他人的条目被跳过
+func (e *FolderExporter) SetAuthorizer(authorizer ExportAuthorizer) {
+	e.authorizer = authorizer
+}
+
+// FolderExport 一次已通过校验的导出，持有并发名额直到Close
+type FolderExport struct {
+	exporter *FolderExporter
+	folder   *models.File
+	access   *exportAccess
+	stats    ExportStats
+	release  func()
+}
+
+// Begin 校验文件夹权限和导出限制，并占用导出名额
+//
+// 在写出任何数据之前完成全部校验，调用方可以据此返回正常的错误响应；
+// userID 为发起导出的用户，导出名额按该用户计算
+func (e *FolderExporter) Begin(ctx context.Context, userID, folderID uint) (*FolderExport, error) {
+	if userID == 0 || folderID == 0 {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "用户ID和文件夹ID不能为空")
+	}
+
+	folder, err := e.fileRepo.GetByID(ctx, folderID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "文件夹不存在")
+		}
+		return nil, fmt.Errorf("获取文件夹失败: %w", err)
+	}
+	if folder.Status == models.FileStatusDeleted {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "文件夹不存在")
+	}
+	if folder.UserID != userID {
+		if e.authorizer == nil {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, "无权访问该文件夹")
+		}
+		if err := e.authorizer.Authorize(ctx, userID, folder.ID, models.FilePermissionRead); err != nil {
+			return nil, err
+		}
+	}
+	if !folder.IsFolder {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "只能导出文件夹")
+	}
+
+	release, err := e.limiter.Acquire(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	access := &exportAccess{userID: userID, decisions: make(map[uint]bool)}
+	stats, err := e.scan(ctx, folder, access)
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	return &FolderExport{exporter: e, folder: folder, access: access, stats: *stats, release: release}, nil
+}
+
+// Folder 返回导出的根文件夹
+func (x *FolderExport) Folder() *models.File {
+	return x.folder
+}
+
+// Stats 返回导出内容统计
+func (x *FolderExport) Stats() ExportStats {
+	return x.stats
+}
+
+// Close 释放导出名额
+func (x *FolderExport) Close() {
+	x.release()
+}
+
+// WriteZip 将文件夹内容以ZIP格式流式写入w
+func (x *FolderExport) WriteZip(ctx context.Context, w io.Writer) (*ExportStats, error) {
+	zw := zip.NewWriter(w)
+	zw.RegisterCompressor(zip.Deflate, newPooledFlateWriter)
+
+	stats := &ExportStats{}
+	skipped, err := x.exporter.walk(ctx, x.folder, x.access, func(file *models.File, name string) error {
+		if file.IsFolder {
+			stats.Folders++
+			_, err := zw.CreateHeader(&zip.FileHeader{Name: name + "/", Modified: file.UpdatedAt})
+			return err
+		}
+
+		stats.Files++
+		n, err := x.exporter.writeFile(ctx, zw, file, name)
+		stats.TotalSize += n
+		return err
+	})
+	stats.Skipped = skipped
+	if err != nil {
+		return stats, err
+	}
+
+	if err := zw.Close(); err != nil {
+		return stats, fmt.Errorf("写入ZIP目录失败: %w", err)
+	}
+	return stats, nil
+}
+
+// scan 预先统计导出内容并检查限制，只保存计数不保存文件列表
+func (e *FolderExporter) scan(ctx context.Context, root *models.File, access *exportAccess) (*ExportStats, error) {
+	stats := &ExportStats{}
+	skipped, err := e.walk(ctx, root, access, func(file *models.File, _ string) error {
+		if file.IsFolder {
+			stats.Folders++
+			return nil
+		}
+		stats.Files++
+		stats.TotalSize += file.Size
+		if stats.Files > e.options.MaxFiles {
+			return &ExportLimitError{Limit: ExportLimitFiles, Max: int64(e.options.MaxFiles), Actual: int64(stats.Files)}
+		}
+		if stats.TotalSize > e.options.MaxTotalSize {
+			return &ExportLimitError{Limit: ExportLimitSize, Max: e.options.MaxTotalSize, Actual: stats.TotalSize}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	stats.Skipped = skipped
+	return stats, nil
+}
+
+// exportAccess 一次导出中发起用户对所有者不同的条目的权限判断结果
+//
+// 统计和写出遍历同一棵树，判断结果在两次遍历间复用，每个条目只检查一次
+type exportAccess struct {
+	userID    uint
+	decisions map[uint]bool
+}
+
+// allowed 判断用户能否导出已允许导出的文件夹parent下的条目file
+func (e *FolderExporter) allowed(ctx context.Context, access *exportAccess, parent, file *models.File) (bool, error) {
+	if file.UserID == access.userID || file.UserID == parent.UserID {
+		return true, nil
+	}
+	if allowed, ok := access.decisions[file.ID]; ok {
+		return allowed, nil
+	}
+	if e.authorizer == nil {
+		return false, nil
+	}
+
+	err := e.authorizer.Authorize(ctx, access.userID, file.ID, models.FilePermissionRead)
+	switch {
+	case err == nil:
+		access.decisions[file.ID] = true
+	case pkgErrors.IsPermissionError(err), pkgErrors.IsNotFoundError(err):
+		access.decisions[file.ID] = false
+	default:
+		return false, fmt.Errorf("检查文件权限失败: %w", err)
+	}
+	return access.decisions[file.ID], nil
+}
+
+// exportable 检查条目内容当前能否导出：上传未完成、已删除或已归档的文件不能导出
+func (e *FolderExporter) exportable(file *models.File) bool {
+	switch file.Status {
+	case models.FileStatusUploading, models.FileStatusError, models.FileStatusDeleted:
+		return false
+	}
+	return file.IsFolder || file.IsReadable(e.now())
+}
+
+// exportFrame 待遍历的文件夹
+type exportFrame struct {
+	folder *models.File
+	name   string
+	depth  int
+}
+
+// walk 深度优先遍历文件夹，按ZIP中的路径回调每个可导出的条目(含根文件夹)，返回跳过的条目数
+//
+// 每次只持有当前文件夹的子项列表，子文件夹入栈后列表即可被回收；跳过的文件夹不再遍历其子项
+func (e *FolderExporter) walk(ctx context.Context, root *models.File, access *exportAccess, visit func(file *models.File, name string) error) (int, error) {
+	skipped := 0
+	stack := []exportFrame{{folder: root, name: sanitizeEntryName(root.Name)}}
+	for len(stack) > 0 {
+		if err := ctx.Err(); err != nil {
+			return skipped, err
+		}
+
+		frame := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if frame.depth > maxFolderDepth {
+			return skipped, fmt.Errorf("文件夹层级超过上限: %d", maxFolderDepth)
+		}
+		if err := visit(frame.folder, frame.name); err != nil {
+			return skipped, err
+		}
+
+		children, err := e.fileRepo.ListChildren(ctx, frame.folder.ID)
+		if err != nil {
+			return skipped, fmt.Errorf("获取子文件失败: %w", err)
+		}
+
+		// 子文件夹逆序入栈，保证按名称顺序导出
+		var folders []exportFrame
+		for _, child := range children {
+			if !e.exportable(child) {
+				skipped++
+				continue
+			}
+			allowed, err := e.allowed(ctx, access, frame.folder, child)
+			if err != nil {
+				return skipped, err
+			}
+			if !allowed {
+				skipped++
+				continue
+			}
+
+			name := frame.name + "/" + sanitizeEntryName(child.Name)
+			if child.IsFolder {
+				folders = append(folders, exportFrame{folder: child, name: name, depth: frame.depth + 1})
+				continue
+			}
+			if err := visit(child, name); err != nil {
+				return skipped, err
+			}
+		}
+		for i := len(folders) - 1; i >= 0; i-- {
+			stack = append(stack, folders[i])
+		}
+	}
+	return skipped, nil
+}
+
+// writeFile 将单个文件从存储复制到ZIP条目
+func (e *FolderExporter) writeFile(ctx context.Context, zw *zip.Writer, file *models.File, name string) (int64, error) {
+	entry, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: file.UpdatedAt})
+	if err != nil {
+		return 0, fmt.Errorf("创建ZIP条目失败: %w", err)
+	}
+
+	if file.StoragePath == nil || *file.StoragePath == "" {
+		if file.Size == 0 {
+			return 0, nil
+		}
+		return 0, pkgErrors.WrapErrorf(pkgErrors.ErrFileNotFound, "文件缺少存储路径: %s", name)
+	}
+
+	reader, err := e.store.Open(ctx, *file.StoragePath)
+	if err != nil {
+		return 0, fmt.Errorf("打开文件 %s 失败: %w", name, err)
+	}
+	defer reader.Close()
+
+	n, err := utils.PooledCopy(entry, reader)
+	if err != nil {
+		return n, fmt.Errorf("写入文件 %s 失败: %w", name, err)
+	}
+	return n, nil
+}
+
+// sanitizeEntryName 清理ZIP条目名称，防止路径穿越
+func sanitizeEntryName(name string) string {
+	name = strings.NewReplacer("/", "_", "\\", "_").Replace(strings.TrimSpace(name))
+	if name == "" || name == "." || name == ".." {
+		return "_"
+	}
+	return name
+}
+
+// flateWriterPool 复用压缩器，每个 flate.Writer 约占用1MB内存
+var flateWriterPool = sync.Pool{
+	New: func() interface{} {
+		w, _ := flate.NewWriter(io.Discard, flate.DefaultCompression)
+		return w
+	},
+}
+
+// pooledFlateWriter 关闭时将压缩器归还到池中
+type pooledFlateWriter struct {
+	*flate.Writer
+}
+
+// newPooledFlateWriter 从池中获取压缩器，作为ZIP的Deflate压缩器
+func newPooledFlateWriter(w io.Writer) (io.WriteCloser, error) {
+	fw := flateWriterPool.Get().(*flate.Writer)
+	fw.Reset(w)
+	return &pooledFlateWriter{Writer: fw}, nil
+}
+
+// Close 完成压缩并归还压缩器
+func (w *pooledFlateWriter) Close() error {
+	if w.Writer == nil {
+		return nil
+	}
+	err := w.Writer.Close()
+	flateWriterPool.Put(w.Writer)
+	w.Writer = nil
+	return err
+}
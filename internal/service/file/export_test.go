@@ -0,0 +1,290 @@
+package file
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/storage"
+	"cloudpan/internal/repository/models"
+)
+
+// exportFixture 构建导出测试用的目录树和存储
+type exportFixture struct {
+	repo   *memoryFileRepository
+	store  *storage.LocalStorage
+	nextID uint
+}
+
+func newExportFixture(t *testing.T) *exportFixture {
+	t.Helper()
+	store, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	return &exportFixture{
+		repo:   &memoryFileRepository{files: make(map[uint]*models.File), children: make(map[uint][]*models.File)},
+		store:  store,
+		nextID: 1,
+	}
+}
+
+func (f *exportFixture) add(t *testing.T, parent *models.File, name string, content *string) *models.File {
+	t.Helper()
+	var parentID *uint
+	if parent != nil {
+		parentID = uintPtr(parent.ID)
+	}
+	file := newTestFile(f.nextID, 7, parentID, name, content == nil)
+	f.nextID++
+	if content != nil {
+		path := fmt.Sprintf("files/%d", file.ID)
+		require.NoError(t, f.store.Put(context.Background(), path, strings.NewReader(*content), int64(len(*content))))
+		file.StoragePath = strPtr(path)
+		file.Size = int64(len(*content))
+	}
+	f.repo.files[file.ID] = file
+	if parent != nil {
+		f.repo.children[parent.ID] = append(f.repo.children[parent.ID], file)
+	}
+	return file
+}
+
+func readZip(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+
+	entries := make(map[string]string)
+	for _, f := range reader.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		rc.Close()
+		entries[f.Name] = string(content)
+	}
+	return entries
+}
+
+func TestFolderExporter_WriteZip(t *testing.T) {
+	fx := newExportFixture(t)
+	root := fx.add(t, nil, "photos", nil)
+	fx.add(t, root, "a.txt", strPtr("alpha"))
+	sub := fx.add(t, root, "2024", nil)
+	fx.add(t, sub, "b.txt", strPtr(strings.Repeat("bravo", 10000)))
+	fx.add(t, sub, "../evil.txt", strPtr("evil"))
+
+	exporter := NewFolderExporter(fx.repo, fx.store, nil, ExportOptions{}, zap.NewNop())
+	export, err := exporter.Begin(context.Background(), 7, root.ID)
+	require.NoError(t, err)
+	defer export.Close()
+
+	assert.Equal(t, ExportStats{Files: 3, Folders: 2, TotalSize: 5 + 50000 + 4}, export.Stats())
+
+	var buf bytes.Buffer
+	stats, err := export.WriteZip(context.Background(), &buf)
+	require.NoError(t, err)
+	assert.Equal(t, export.Stats(), *stats)
+
+	entries := readZip(t, buf.Bytes())
+	assert.Equal(t, map[string]string{
+		"photos/":                 "",
+		"photos/a.txt":            "alpha",
+		"photos/2024/":            "",
+		"photos/2024/b.txt":       strings.Repeat("bravo", 10000),
+		"photos/2024/.._evil.txt": "evil",
+	}, entries)
+}
+
+func TestFolderExporter_Begin(t *testing.T) {
+	ctx := context.Background()
+	fx := newExportFixture(t)
+	root := fx.add(t, nil, "docs", nil)
+	doc := fx.add(t, root, "a.txt", strPtr("alpha"))
+	fx.add(t, root, "b.txt", strPtr("bravo"))
+
+	t.Run("rejects other users", func(t *testing.T) {
+		exporter := NewFolderExporter(fx.repo, fx.store, nil, ExportOptions{}, zap.NewNop())
+		_, err := exporter.Begin(ctx, 8, root.ID)
+		assert.True(t, pkgErrors.IsPermissionError(err))
+	})
+
+	t.Run("rejects regular files", func(t *testing.T) {
+		exporter := NewFolderExporter(fx.repo, fx.store, nil, ExportOptions{}, zap.NewNop())
+		_, err := exporter.Begin(ctx, 7, doc.ID)
+		assert.True(t, pkgErrors.IsValidationError(err))
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		exporter := NewFolderExporter(fx.repo, fx.store, nil, ExportOptions{}, zap.NewNop())
+		_, err := exporter.Begin(ctx, 7, 999)
+		assert.True(t, pkgErrors.IsNotFoundError(err))
+	})
+
+	t.Run("enforces file limit and releases slot", func(t *testing.T) {
+		limiter := NewExportLimiter(1, 10)
+		exporter := NewFolderExporter(fx.repo, fx.store, limiter, ExportOptions{MaxFiles: 1}, zap.NewNop())
+		_, err := exporter.Begin(ctx, 7, root.ID)
+		assert.ErrorIs(t, err, pkgErrors.ErrQuotaExceeded)
+		assert.Zero(t, limiter.Active(7))
+	})
+
+	t.Run("enforces size limit", func(t *testing.T) {
+		exporter := NewFolderExporter(fx.repo, fx.store, nil, ExportOptions{MaxTotalSize: 6}, zap.NewNop())
+		_, err := exporter.Begin(ctx, 7, root.ID)
+		assert.ErrorIs(t, err, pkgErrors.ErrQuotaExceeded)
+	})
+
+	t.Run("limits concurrent exports per user", func(t *testing.T) {
+		limiter := NewExportLimiter(1, 10)
+		exporter := NewFolderExporter(fx.repo, fx.store, limiter, ExportOptions{}, zap.NewNop())
+
+		first, err := exporter.Begin(ctx, 7, root.ID)
+		require.NoError(t, err)
+		_, err = exporter.Begin(ctx, 7, root.ID)
+		assert.True(t, pkgErrors.IsRateLimitError(err))
+
+		first.Close()
+		first.Close()
+		assert.Zero(t, limiter.Active(7))
+
+		second, err := exporter.Begin(ctx, 7, root.ID)
+		require.NoError(t, err)
+		second.Close()
+	})
+}
+
+// stubExportAuthorizer 按文件ID授予read权限
+type stubExportAuthorizer struct {
+	readable map[uint]bool
+	calls    int
+}
+
+func (a *stubExportAuthorizer) Authorize(_ context.Context, _ uint, fileID uint, _ string) error {
+	a.calls++
+	if a.readable[fileID] {
+		return nil
+	}
+	return pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, "无权访问该文件")
+}
+
+func TestFolderExporter_EntryPermissions(t *testing.T) {
+	ctx := context.Background()
+	fx := newExportFixture(t)
+	root := fx.add(t, nil, "shared", nil)
+	fx.add(t, root, "a.txt", strPtr("alpha"))
+	sub := fx.add(t, root, "sub", nil)
+	fx.add(t, sub, "b.txt", strPtr("bravo"))
+	// 其他用户放入的条目：9的文件授权给了8，10的文件夹没有授权
+	granted := fx.add(t, root, "granted.txt", strPtr("grant"))
+	granted.UserID = 9
+	private := fx.add(t, root, "private", nil)
+	private.UserID = 10
+	fx.add(t, private, "secret.txt", strPtr("secret")).UserID = 10
+	// 上传未完成和已归档的文件
+	fx.add(t, root, "partial.bin", strPtr("part")).Status = models.FileStatusUploading
+	archivedAt := time.Now().Add(-time.Hour)
+	fx.add(t, root, "cold.bin", strPtr("cold")).ArchivedAt = &archivedAt
+
+	authorizer := &stubExportAuthorizer{readable: map[uint]bool{root.ID: true, granted.ID: true}}
+	exporter := NewFolderExporter(fx.repo, fx.store, nil, ExportOptions{}, zap.NewNop())
+
+	// 未设置授权服务时不能导出他人的文件夹
+	_, err := exporter.Begin(ctx, 8, root.ID)
+	assert.True(t, pkgErrors.IsPermissionError(err))
+
+	exporter.SetAuthorizer(authorizer)
+	export, err := exporter.Begin(ctx, 8, root.ID)
+	require.NoError(t, err)
+	defer export.Close()
+	assert.Equal(t, ExportStats{Files: 3, Folders: 2, TotalSize: 15, Skipped: 3}, export.Stats())
+
+	var buf bytes.Buffer
+	stats, err := export.WriteZip(ctx, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, export.Stats(), *stats)
+	assert.Equal(t, map[string]string{
+		"shared/":            "",
+		"shared/a.txt":       "alpha",
+		"shared/granted.txt": "grant",
+		"shared/sub/":        "",
+		"shared/sub/b.txt":   "bravo",
+	}, readZip(t, buf.Bytes()))
+	// 根文件夹和两个所有者不同的条目各检查一次，写出时复用统计时的结果
+	assert.Equal(t, 3, authorizer.calls)
+}
+
+func TestFolderExporter_LimitError(t *testing.T) {
+	fx := newExportFixture(t)
+	root := fx.add(t, nil, "docs", nil)
+	fx.add(t, root, "a.txt", strPtr(strings.Repeat("a", 2048)))
+
+	exporter := NewFolderExporter(fx.repo, fx.store, nil, ExportOptions{MaxTotalSize: 1024}, zap.NewNop())
+	_, err := exporter.Begin(context.Background(), 7, root.ID)
+
+	var limitErr *ExportLimitError
+	require.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, &ExportLimitError{Limit: ExportLimitSize, Max: 1024, Actual: 2048}, limitErr)
+	assert.Equal(t, "文件夹大小超过打包下载上限 1.0 KB(至少 2.0 KB)，请分别下载子文件夹", err.Error())
+}
+
+func TestExportLimiter_Total(t *testing.T) {
+	limiter := NewExportLimiter(2, 2)
+	releaseA, err := limiter.Acquire(1)
+	require.NoError(t, err)
+	_, err = limiter.Acquire(2)
+	require.NoError(t, err)
+
+	_, err = limiter.Acquire(3)
+	assert.True(t, pkgErrors.IsRateLimitError(err))
+
+	releaseA()
+	_, err = limiter.Acquire(3)
+	assert.NoError(t, err)
+}
+
+func BenchmarkFolderExporter_WriteZip(b *testing.B) {
+	store, err := storage.NewLocalStorage(b.TempDir())
+	if err != nil {
+		b.Fatal(err)
+	}
+	repo := &memoryFileRepository{files: make(map[uint]*models.File), children: make(map[uint][]*models.File)}
+	root := newTestFile(1, 7, nil, "root", true)
+	repo.files[1] = root
+	content := strings.Repeat("cloudpan export benchmark ", 4096)
+	for i := uint(2); i < 52; i++ {
+		path := fmt.Sprintf("files/%d", i)
+		if err := store.Put(context.Background(), path, strings.NewReader(content), int64(len(content))); err != nil {
+			b.Fatal(err)
+		}
+		file := newTestFile(i, 7, uintPtr(1), fmt.Sprintf("file-%d.txt", i), false)
+		file.StoragePath = strPtr(path)
+		file.Size = int64(len(content))
+		repo.files[i] = file
+		repo.children[1] = append(repo.children[1], file)
+	}
+
+	exporter := NewFolderExporter(repo, store, nil, ExportOptions{}, zap.NewNop())
+	b.ReportAllocs()
+	b.SetBytes(int64(len(content)) * 50)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		export, err := exporter.Begin(context.Background(), 7, 1)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := export.WriteZip(context.Background(), io.Discard); err != nil {
+			b.Fatal(err)
+		}
+		export.Close()
+	}
+}
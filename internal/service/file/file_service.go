@@ -0,0 +1,34 @@
+package file
+
+import (
+	"context"
+	"time"
+)
+
+// FileService 文件服务接口
+//
+// 提供文件相关的业务逻辑操作，包括：
+// 1. 文件夹完整性校验：计算并缓存子树组合校验和
+// 2. 校验和失效：文件变更时沿父链向上标记需要重新计算
+//
+// 使用示例：
+//
+//	service := NewFileService(fileRepo, logger)
+//	checksum, err := service.GetFolderChecksum(ctx, userID, folderID)
+//	err = service.InvalidateChecksums(ctx, changedFileID)
+type FileService interface {
+	// 文件夹完整性校验
+	GetFolderChecksum(ctx context.Context, userID, folderID uint) (*FolderChecksum, error)
+	InvalidateChecksums(ctx context.Context, fileID uint) error
+}
+
+// FolderChecksum 文件夹组合校验和信息
+type FolderChecksum struct {
+	FolderID   uint      `json:"folder_id"`   // 文件夹ID
+	FolderUUID string    `json:"folder_uuid"` // 文件夹UUID
+	Algorithm  string    `json:"algorithm"`   // 校验算法
+	Checksum   string    `json:"checksum"`    // 组合校验和
+	ChildCount int       `json:"child_count"` // 直接子项数量
+	Cached     bool      `json:"cached"`      // 是否命中已保存的校验和
+	ComputedAt time.Time `json:"computed_at"` // 计算时间
+}
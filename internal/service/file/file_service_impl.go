@@ -0,0 +1,171 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	filerepo "cloudpan/internal/repository/file"
+	"cloudpan/internal/repository/models"
+)
+
+// maxFolderDepth 文件夹遍历的最大深度，防止异常数据导致无限递归
+const maxFolderDepth = 256
+
+// fileService 文件服务实现
+type fileService struct {
+	fileRepo filerepo.FileRepository
+	logger   *zap.Logger
+}
+
+// NewFileService 创建文件服务实例
+func NewFileService(fileRepo filerepo.FileRepository, logger *zap.Logger) FileService {
+	return &fileService{
+		fileRepo: fileRepo,
+		logger:   logger,
+	}
+}
+
+// GetFolderChecksum 获取文件夹子树的组合校验和
+//
+// 已保存的校验和直接返回；失效的文件夹会递归重新计算，
+// 子文件夹中仍然有效的校验和会被复用，因此只需重算变更路径上的节点
+func (s *fileService) GetFolderChecksum(ctx context.Context, userID, folderID uint) (*FolderChecksum, error) {
+	folder, err := s.getOwnedFile(ctx, userID, folderID)
+	if err != nil {
+		return nil, err
+	}
+	if !folder.IsFolder {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "只能计算文件夹的校验和")
+	}
+
+	cached := folder.ContentChecksum != nil && *folder.ContentChecksum != ""
+	checksum, childCount, err := s.computeFolderChecksum(ctx, folder, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	computedAt := time.Now()
+	if cached && folder.ChecksumUpdatedAt != nil {
+		computedAt = *folder.ChecksumUpdatedAt
+	}
+
+	return &FolderChecksum{
+		FolderID:   folder.ID,
+		FolderUUID: folder.UUID,
+		Algorithm:  FolderChecksumAlgorithm,
+		Checksum:   checksum,
+		ChildCount: childCount,
+		Cached:     cached,
+		ComputedAt: computedAt,
+	}, nil
+}
+
+// InvalidateChecksums 文件变更后使父链上的文件夹校验和失效
+func (s *fileService) InvalidateChecksums(ctx context.Context, fileID uint) error {
+	if fileID == 0 {
+		return fmt.Errorf("文件ID不能为空")
+	}
+
+	file, err := s.fileRepo.GetByID(ctx, fileID)
+	if err != nil {
+		return fmt.Errorf("获取文件失败: %w", err)
+	}
+
+	var ids []uint
+	if file.IsFolder {
+		ids = append(ids, file.ID)
+	}
+
+	parentID := file.ParentID
+	for depth := 0; parentID != nil && depth < maxFolderDepth; depth++ {
+		ids = append(ids, *parentID)
+		parent, err := s.fileRepo.GetByID(ctx, *parentID)
+		if err != nil {
+			// 父文件夹已被删除时停止向上传播
+			break
+		}
+		parentID = parent.ParentID
+	}
+
+	if err := s.fileRepo.ClearChecksums(ctx, ids); err != nil {
+		return fmt.Errorf("清除文件夹校验和失败: %w", err)
+	}
+
+	return nil
+}
+
+// 辅助方法
+
+// getOwnedFile 获取属于指定用户的文件
+func (s *fileService) getOwnedFile(ctx context.Context, userID, fileID uint) (*models.File, error) {
+	if userID == 0 || fileID == 0 {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "用户ID和文件ID不能为空")
+	}
+
+	file, err := s.fileRepo.GetByID(ctx, fileID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "文件不存在")
+		}
+		return nil, fmt.Errorf("获取文件失败: %w", err)
+	}
+
+	if file.UserID != userID {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, "无权访问该文件")
+	}
+
+	return file, nil
+}
+
+// computeFolderChecksum 递归计算文件夹校验和，返回校验和和直接子项数量
+func (s *fileService) computeFolderChecksum(ctx context.Context, folder *models.File, depth int) (string, int, error) {
+	if depth > maxFolderDepth {
+		return "", 0, fmt.Errorf("文件夹层级超过上限: %d", maxFolderDepth)
+	}
+
+	children, err := s.fileRepo.ListChildren(ctx, folder.ID)
+	if err != nil {
+		return "", 0, fmt.Errorf("获取子项失败: %w", err)
+	}
+
+	if folder.ContentChecksum != nil && *folder.ContentChecksum != "" {
+		return *folder.ContentChecksum, len(children), nil
+	}
+
+	entries := make([]ChecksumEntry, 0, len(children))
+	for _, child := range children {
+		entry := ChecksumEntry{
+			Name:     child.Name,
+			IsFolder: child.IsFolder,
+		}
+		if child.IsFolder {
+			childChecksum, _, err := s.computeFolderChecksum(ctx, child, depth+1)
+			if err != nil {
+				return "", 0, err
+			}
+			entry.Hash = childChecksum
+		} else {
+			entry.Size = child.Size
+			if child.Hash != nil {
+				entry.Hash = *child.Hash
+			}
+		}
+		entries = append(entries, entry)
+	}
+
+	checksum := ComputeCompositeChecksum(entries)
+	if err := s.fileRepo.UpdateChecksum(ctx, folder.ID, checksum, time.Now()); err != nil {
+		// 保存失败不影响本次结果，下次请求会重新计算
+		s.logger.Warn("Failed to persist folder checksum",
+			zap.Uint("folder_id", folder.ID),
+			zap.Error(err))
+	}
+
+	return checksum, len(children), nil
+}
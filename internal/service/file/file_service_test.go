@@ -0,0 +1,450 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	basemodels "cloudpan/internal/pkg/database/models"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/repository/models"
+)
+
+// MockFileRepository 模拟文件仓储
+type MockFileRepository struct {
+	mock.Mock
+}
+
+func (m *MockFileRepository) GetByID(ctx context.Context, id uint) (*models.File, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.File), args.Error(1)
+}
+
+func (m *MockFileRepository) GetByUUID(ctx context.Context, uuid string) (*models.File, error) {
+	args := m.Called(ctx, uuid)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.File), args.Error(1)
+}
+
+func (m *MockFileRepository) ListChildren(ctx context.Context, parentID uint) ([]*models.File, error) {
+	args := m.Called(ctx, parentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.File), args.Error(1)
+}
+
+func (m *MockFileRepository) UpdateChecksum(ctx context.Context, id uint, checksum string, computedAt time.Time) error {
+	args := m.Called(ctx, id, checksum, computedAt)
+	return args.Error(0)
+}
+
+func (m *MockFileRepository) ClearChecksums(ctx context.Context, ids []uint) error {
+	args := m.Called(ctx, ids)
+	return args.Error(0)
+}
+
+func (m *MockFileRepository) Create(ctx context.Context, file *models.File) error {
+	args := m.Called(ctx, file)
+	return args.Error(0)
+}
+
+func (m *MockFileRepository) CompleteUpload(ctx context.Context, id uint, size int64) (bool, error) {
+	args := m.Called(ctx, id, size)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockFileRepository) FailUpload(ctx context.Context, id uint) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockFileRepository) UpdateUploadMetadata(ctx context.Context, id uint, metadata *basemodels.JSONMap) error {
+	args := m.Called(ctx, id, metadata)
+	return args.Error(0)
+}
+
+func (m *MockFileRepository) DeleteUpload(ctx context.Context, file *models.File) (bool, error) {
+	args := m.Called(ctx, file)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockFileRepository) ListStaleUploads(ctx context.Context, createdBefore time.Time, limit int) ([]*models.File, error) {
+	args := m.Called(ctx, createdBefore, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.File), args.Error(1)
+}
+
+func (m *MockFileRepository) ListArchiveCandidates(ctx context.Context, inactiveBefore time.Time, minSize, maxSize int64, limit int) ([]*models.File, error) {
+	args := m.Called(ctx, inactiveBefore, minSize, maxSize, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.File), args.Error(1)
+}
+
+func (m *MockFileRepository) MarkArchived(ctx context.Context, id uint, storageClass string, archivedAt time.Time) error {
+	args := m.Called(ctx, id, storageClass, archivedAt)
+	return args.Error(0)
+}
+
+func (m *MockFileRepository) UpdateRestoreState(ctx context.Context, id uint, requestedAt, restoredUntil *time.Time) error {
+	args := m.Called(ctx, id, requestedAt, restoredUntil)
+	return args.Error(0)
+}
+
+func (m *MockFileRepository) RecordDownload(ctx context.Context, id uint, at time.Time) error {
+	args := m.Called(ctx, id, at)
+	return args.Error(0)
+}
+
+func (m *MockFileRepository) UpdatePreviewURLs(ctx context.Context, id uint, thumbnailURL, previewURL *string) error {
+	args := m.Called(ctx, id, thumbnailURL, previewURL)
+	return args.Error(0)
+}
+
+func (m *MockFileRepository) UpdateScanStatus(ctx context.Context, id uint, status string) error {
+	args := m.Called(ctx, id, status)
+	return args.Error(0)
+}
+
+func (m *MockFileRepository) UpdatePreviewStatus(ctx context.Context, id uint, status string) error {
+	args := m.Called(ctx, id, status)
+	return args.Error(0)
+}
+
+func (m *MockFileRepository) UpdateTags(ctx context.Context, id uint, tags *string) error {
+	args := m.Called(ctx, id, tags)
+	return args.Error(0)
+}
+
+func (m *MockFileRepository) UpdateMetadata(ctx context.Context, id uint, metadata *basemodels.JSONMap) error {
+	args := m.Called(ctx, id, metadata)
+	return args.Error(0)
+}
+
+// 测试辅助函数
+func newTestFile(id, userID uint, parentID *uint, name string, isFolder bool) *models.File {
+	f := &models.File{
+		UserID:   userID,
+		ParentID: parentID,
+		Name:     name,
+		IsFolder: isFolder,
+	}
+	f.ID = id
+	return f
+}
+
+func uintPtr(v uint) *uint {
+	return &v
+}
+
+func strPtr(v string) *string {
+	return &v
+}
+
+func TestComputeCompositeChecksum(t *testing.T) {
+	t.Run("order independent", func(t *testing.T) {
+		a := []ChecksumEntry{
+			{Name: "b.txt", Size: 2, Hash: "BB"},
+			{Name: "a.txt", Size: 1, Hash: "aa"},
+		}
+		b := []ChecksumEntry{
+			{Name: "a.txt", Size: 1, Hash: "AA"},
+			{Name: "b.txt", Size: 2, Hash: "bb"},
+		}
+		assert.Equal(t, ComputeCompositeChecksum(a), ComputeCompositeChecksum(b))
+	})
+
+	t.Run("content sensitive", func(t *testing.T) {
+		base := []ChecksumEntry{{Name: "a.txt", Size: 1, Hash: "aa"}}
+		changed := []ChecksumEntry{{Name: "a.txt", Size: 1, Hash: "ab"}}
+		renamed := []ChecksumEntry{{Name: "c.txt", Size: 1, Hash: "aa"}}
+		assert.NotEqual(t, ComputeCompositeChecksum(base), ComputeCompositeChecksum(changed))
+		assert.NotEqual(t, ComputeCompositeChecksum(base), ComputeCompositeChecksum(renamed))
+	})
+
+	t.Run("folder and file with same name differ", func(t *testing.T) {
+		asFile := []ChecksumEntry{{Name: "x", Hash: "aa"}}
+		asFolder := []ChecksumEntry{{Name: "x", IsFolder: true, Hash: "aa"}}
+		assert.NotEqual(t, ComputeCompositeChecksum(asFile), ComputeCompositeChecksum(asFolder))
+	})
+
+	t.Run("empty folder", func(t *testing.T) {
+		assert.Len(t, ComputeCompositeChecksum(nil), 64)
+	})
+}
+
+func TestGetFolderChecksum(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("computes recursively and reuses cached subfolders", func(t *testing.T) {
+		repo := new(MockFileRepository)
+		service := NewFileService(repo, zap.NewNop())
+
+		root := newTestFile(1, 7, nil, "root", true)
+		cachedSub := newTestFile(2, 7, uintPtr(1), "sub", true)
+		cachedSub.ContentChecksum = strPtr("cafebabe")
+		doc := newTestFile(3, 7, uintPtr(1), "doc.txt", false)
+		doc.Size = 10
+		doc.Hash = strPtr("abc")
+
+		repo.On("GetByID", ctx, uint(1)).Return(root, nil)
+		repo.On("ListChildren", ctx, uint(1)).Return([]*models.File{cachedSub, doc}, nil)
+		repo.On("ListChildren", ctx, uint(2)).Return([]*models.File{}, nil)
+		repo.On("UpdateChecksum", ctx, uint(1), mock.Anything, mock.Anything).Return(nil)
+
+		result, err := service.GetFolderChecksum(ctx, 7, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, FolderChecksumAlgorithm, result.Algorithm)
+		assert.Equal(t, 2, result.ChildCount)
+		assert.False(t, result.Cached)
+
+		expected := ComputeCompositeChecksum([]ChecksumEntry{
+			{Name: "sub", IsFolder: true, Hash: "cafebabe"},
+			{Name: "doc.txt", Size: 10, Hash: "abc"},
+		})
+		assert.Equal(t, expected, result.Checksum)
+		repo.AssertNotCalled(t, "UpdateChecksum", ctx, uint(2), mock.Anything, mock.Anything)
+	})
+
+	t.Run("returns cached checksum", func(t *testing.T) {
+		repo := new(MockFileRepository)
+		service := NewFileService(repo, zap.NewNop())
+
+		computedAt := time.Now().Add(-time.Hour)
+		root := newTestFile(1, 7, nil, "root", true)
+		root.ContentChecksum = strPtr("deadbeef")
+		root.ChecksumUpdatedAt = &computedAt
+
+		repo.On("GetByID", ctx, uint(1)).Return(root, nil)
+		repo.On("ListChildren", ctx, uint(1)).Return([]*models.File{}, nil)
+
+		result, err := service.GetFolderChecksum(ctx, 7, 1)
+		assert.NoError(t, err)
+		assert.True(t, result.Cached)
+		assert.Equal(t, "deadbeef", result.Checksum)
+		assert.Equal(t, computedAt, result.ComputedAt)
+	})
+
+	t.Run("rejects other users", func(t *testing.T) {
+		repo := new(MockFileRepository)
+		service := NewFileService(repo, zap.NewNop())
+
+		repo.On("GetByID", ctx, uint(1)).Return(newTestFile(1, 8, nil, "root", true), nil)
+
+		_, err := service.GetFolderChecksum(ctx, 7, 1)
+		assert.True(t, pkgErrors.IsPermissionError(err))
+	})
+
+	t.Run("rejects regular files", func(t *testing.T) {
+		repo := new(MockFileRepository)
+		service := NewFileService(repo, zap.NewNop())
+
+		repo.On("GetByID", ctx, uint(1)).Return(newTestFile(1, 7, nil, "a.txt", false), nil)
+
+		_, err := service.GetFolderChecksum(ctx, 7, 1)
+		assert.True(t, pkgErrors.IsValidationError(err))
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		repo := new(MockFileRepository)
+		service := NewFileService(repo, zap.NewNop())
+
+		repo.On("GetByID", ctx, uint(1)).Return(nil, gorm.ErrRecordNotFound)
+
+		_, err := service.GetFolderChecksum(ctx, 7, 1)
+		assert.True(t, pkgErrors.IsNotFoundError(err))
+	})
+}
+
+func TestInvalidateChecksums(t *testing.T) {
+	ctx := context.Background()
+	repo := new(MockFileRepository)
+	service := NewFileService(repo, zap.NewNop())
+
+	repo.On("GetByID", ctx, uint(3)).Return(newTestFile(3, 7, uintPtr(2), "doc.txt", false), nil)
+	repo.On("GetByID", ctx, uint(2)).Return(newTestFile(2, 7, uintPtr(1), "sub", true), nil)
+	repo.On("GetByID", ctx, uint(1)).Return(newTestFile(1, 7, nil, "root", true), nil)
+	repo.On("ClearChecksums", ctx, []uint{2, 1}).Return(nil)
+
+	assert.NoError(t, service.InvalidateChecksums(ctx, 3))
+	repo.AssertExpectations(t)
+}
+
+// memoryFileRepository 内存文件仓储，避免mock开销影响基准测试结果
+type memoryFileRepository struct {
+	files    map[uint]*models.File
+	children map[uint][]*models.File
+}
+
+func (r *memoryFileRepository) GetByID(_ context.Context, id uint) (*models.File, error) {
+	if f, ok := r.files[id]; ok {
+		return f, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memoryFileRepository) GetByUUID(context.Context, string) (*models.File, error) {
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memoryFileRepository) ListChildren(_ context.Context, parentID uint) ([]*models.File, error) {
+	return r.children[parentID], nil
+}
+
+// UpdateChecksum 不保存结果，每次迭代都重新计算
+func (r *memoryFileRepository) UpdateChecksum(context.Context, uint, string, time.Time) error {
+	return nil
+}
+
+func (r *memoryFileRepository) ClearChecksums(context.Context, []uint) error {
+	return nil
+}
+
+func (r *memoryFileRepository) Create(context.Context, *models.File) error {
+	return nil
+}
+
+func (r *memoryFileRepository) CompleteUpload(context.Context, uint, int64) (bool, error) {
+	return false, nil
+}
+
+func (r *memoryFileRepository) FailUpload(context.Context, uint) error {
+	return nil
+}
+
+func (r *memoryFileRepository) UpdateUploadMetadata(context.Context, uint, *basemodels.JSONMap) error {
+	return nil
+}
+
+func (r *memoryFileRepository) DeleteUpload(context.Context, *models.File) (bool, error) {
+	return false, nil
+}
+
+func (r *memoryFileRepository) ListStaleUploads(context.Context, time.Time, int) ([]*models.File, error) {
+	return nil, nil
+}
+
+func (r *memoryFileRepository) ListArchiveCandidates(context.Context, time.Time, int64, int64, int) ([]*models.File, error) {
+	return nil, nil
+}
+
+func (r *memoryFileRepository) MarkArchived(context.Context, uint, string, time.Time) error {
+	return nil
+}
+
+func (r *memoryFileRepository) UpdateRestoreState(context.Context, uint, *time.Time, *time.Time) error {
+	return nil
+}
+
+func (r *memoryFileRepository) RecordDownload(_ context.Context, id uint, at time.Time) error {
+	if f, ok := r.files[id]; ok {
+		f.DownloadCount++
+		f.LastAccessedAt = &at
+	}
+	return nil
+}
+
+func (r *memoryFileRepository) UpdateScanStatus(_ context.Context, id uint, status string) error {
+	if f, ok := r.files[id]; ok {
+		f.ScanStatus = status
+	}
+	return nil
+}
+
+func (r *memoryFileRepository) UpdatePreviewStatus(_ context.Context, id uint, status string) error {
+	if f, ok := r.files[id]; ok {
+		f.PreviewStatus = status
+	}
+	return nil
+}
+
+func (r *memoryFileRepository) UpdatePreviewURLs(_ context.Context, id uint, thumbnailURL, previewURL *string) error {
+	if f, ok := r.files[id]; ok {
+		f.ThumbnailURL = thumbnailURL
+		f.PreviewURL = previewURL
+	}
+	return nil
+}
+
+func (r *memoryFileRepository) UpdateTags(_ context.Context, id uint, tags *string) error {
+	if f, ok := r.files[id]; ok {
+		f.Tags = tags
+	}
+	return nil
+}
+
+func (r *memoryFileRepository) UpdateMetadata(_ context.Context, id uint, metadata *basemodels.JSONMap) error {
+	if f, ok := r.files[id]; ok {
+		f.Metadata = metadata
+	}
+	return nil
+}
+
+// newFolderTree 构建 folders 个子文件夹、每个文件夹 filesPerFolder 个文件的目录树
+func newFolderTree(folders, filesPerFolder int) *memoryFileRepository {
+	repo := &memoryFileRepository{files: make(map[uint]*models.File), children: make(map[uint][]*models.File)}
+	nextID := uint(1)
+	add := func(parentID *uint, name string, isFolder bool) *models.File {
+		f := newTestFile(nextID, 7, parentID, name, isFolder)
+		if !isFolder {
+			f.Size = int64(nextID) * 1024
+			f.Hash = strPtr(fmt.Sprintf("%064x", nextID))
+		}
+		repo.files[f.ID] = f
+		if parentID != nil {
+			repo.children[*parentID] = append(repo.children[*parentID], f)
+		}
+		nextID++
+		return f
+	}
+
+	root := add(nil, "root", true)
+	for i := 0; i < folders; i++ {
+		folder := add(uintPtr(root.ID), fmt.Sprintf("folder-%d", i), true)
+		for j := 0; j < filesPerFolder; j++ {
+			add(uintPtr(folder.ID), fmt.Sprintf("file-%d.txt", j), false)
+		}
+	}
+	return repo
+}
+
+func BenchmarkComputeCompositeChecksum(b *testing.B) {
+	entries := make([]ChecksumEntry, 1000)
+	for i := range entries {
+		entries[i] = ChecksumEntry{Name: fmt.Sprintf("file-%d.txt", i), Size: int64(i), Hash: fmt.Sprintf("%064x", i)}
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ComputeCompositeChecksum(entries)
+	}
+}
+
+func BenchmarkGetFolderChecksum(b *testing.B) {
+	service := NewFileService(newFolderTree(20, 50), zap.NewNop())
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := service.GetFolderChecksum(ctx, 7, 1); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,281 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/config"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	filerepo "cloudpan/internal/repository/file"
+	"cloudpan/internal/repository/models"
+)
+
+// 文件夹限制默认值
+const (
+	DefaultMaxFolderDepth    = 64
+	DefaultMaxFolderChildren = 100000
+	DefaultFolderWarnRatio   = 0.8
+
+	// DefaultFolderReportLimit 报告中每类文件夹的默认最大条数
+	DefaultFolderReportLimit = 100
+	// MaxFolderReportLimit 报告中每类文件夹的最大条数
+	MaxFolderReportLimit = 1000
+)
+
+// 违反的文件夹限制
+const (
+	FolderLimitDepth    = "depth"    // 嵌套层级
+	FolderLimitChildren = "children" // 直接子项数
+)
+
+// FolderLimiter 文件夹层级和子项数限制接口
+//
+// 过深的嵌套和子项过多的文件夹会拖慢路径查询和文件夹浏览，新建文件夹、移动、复制和上传前检查：
+// 1. 层级：根目录下的文件夹为第1层，移动或复制文件夹时按其子树中最深的文件夹计算
+// 2. 子项数：目标文件夹(含根目录)未删除的直接子项数，包括上传中的文件
+// 3. 报告：列出层级或子项数达到上限一定比例的文件夹，供管理员提前处理
+//
+// 检查和写入不在同一事务中，并发写入时子项数可能略微超过上限；已超过上限的文件夹不受影响，只是不能再增加子项
+//
+// 使用示例：
+//
+//	limiter := NewFolderLimiter(folderRepo, fileRepo, FolderLimitOptionsFromConfig(cfg.Storage.Folders), logger)
+//	err := limiter.CheckDepth(parent.GetFullPath(), 1)
+//	err = limiter.CheckChildren(ctx, userID, &parentID, 1)
+//	report, err := limiter.Report(ctx, 0, 100)
+type FolderLimiter interface {
+	Limits() FolderLimitOptions
+	CheckDepth(parentPath string, height int) error
+	CheckChildren(ctx context.Context, userID uint, parentID *uint, adding int) error
+	Report(ctx context.Context, ratio float64, limit int) (*FolderLimitReport, error)
+}
+
+// FolderLimitOptions 文件夹限制选项
+type FolderLimitOptions struct {
+	MaxDepth    int     // 最大嵌套层级，不超过遍历上限256
+	MaxChildren int     // 单个文件夹的最大直接子项数
+	WarnRatio   float64 // 报告默认列出达到上限该比例的文件夹
+}
+
+// FolderLimitOptionsFromConfig 从文件夹限制配置生成选项
+func FolderLimitOptionsFromConfig(cfg config.FolderLimitsConfig) FolderLimitOptions {
+	return FolderLimitOptions{
+		MaxDepth:    cfg.MaxDepth,
+		MaxChildren: cfg.MaxChildren,
+		WarnRatio:   cfg.WarnRatio,
+	}
+}
+
+// FolderLimitError 超过文件夹限制
+type FolderLimitError struct {
+	Limit  string `json:"limit"`  // 违反的限制(FolderLimitDepth/FolderLimitChildren)
+	Max    int    `json:"max"`    // 上限
+	Actual int64  `json:"actual"` // 操作完成后的层级或子项数
+}
+
+// Error 返回错误信息
+func (e *FolderLimitError) Error() string {
+	if e.Limit == FolderLimitDepth {
+		return fmt.Sprintf("文件夹层级不能超过 %d 层，操作后将达到 %d 层", e.Max, e.Actual)
+	}
+	return fmt.Sprintf("文件夹最多包含 %d 个子项，操作后将达到 %d 个", e.Max, e.Actual)
+}
+
+// Unwrap 归类为不允许的操作
+func (e *FolderLimitError) Unwrap() error {
+	return pkgErrors.ErrOperationNotAllowed
+}
+
+// FolderLimitReport 接近限制的文件夹报告
+type FolderLimitReport struct {
+	MaxDepth       int           `json:"max_depth"`       // 最大嵌套层级
+	MaxChildren    int           `json:"max_children"`    // 最大直接子项数
+	Ratio          float64       `json:"ratio"`           // 列出达到上限该比例的文件夹
+	DeepFolders    []FolderUsage `json:"deep_folders"`    // 层级接近上限的文件夹，按层级降序
+	CrowdedFolders []FolderUsage `json:"crowded_folders"` // 子项数接近上限的文件夹，按子项数降序
+}
+
+// FolderUsage 文件夹的层级或子项数占用情况
+type FolderUsage struct {
+	UserID   uint    `json:"user_id"`             // 所属用户ID
+	FolderID *uint   `json:"folder_id,omitempty"` // 文件夹ID，根目录时省略
+	Path     string  `json:"path"`                // 文件夹完整路径
+	Depth    int     `json:"depth,omitempty"`     // 嵌套层级
+	Children int64   `json:"children,omitempty"`  // 直接子项数
+	Usage    float64 `json:"usage"`               // 占上限的比例，大于1表示限制生效前已超过
+}
+
+// folderLimiter 文件夹限制实现
+type folderLimiter struct {
+	folderRepo filerepo.FolderRepository
+	fileRepo   filerepo.FileRepository
+	options    FolderLimitOptions
+	logger     *zap.Logger
+}
+
+// NewFolderLimiter 创建文件夹限制实例，未配置的选项使用默认值
+func NewFolderLimiter(folderRepo filerepo.FolderRepository, fileRepo filerepo.FileRepository, options FolderLimitOptions, logger *zap.Logger) FolderLimiter {
+	if options.MaxDepth <= 0 {
+		options.MaxDepth = DefaultMaxFolderDepth
+	}
+	if options.MaxDepth > maxFolderDepth {
+		options.MaxDepth = maxFolderDepth
+	}
+	if options.MaxChildren <= 0 {
+		options.MaxChildren = DefaultMaxFolderChildren
+	}
+	if options.WarnRatio <= 0 || options.WarnRatio > 1 {
+		options.WarnRatio = DefaultFolderWarnRatio
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &folderLimiter{
+		folderRepo: folderRepo,
+		fileRepo:   fileRepo,
+		options:    options,
+		logger:     logger,
+	}
+}
+
+// Limits 返回生效的限制
+func (l *folderLimiter) Limits() FolderLimitOptions {
+	return l.options
+}
+
+// CheckDepth 检查放入parentPath后的层级，height为放入的子树包含的文件夹层数，文件为0
+func (l *folderLimiter) CheckDepth(parentPath string, height int) error {
+	if height <= 0 {
+		return nil
+	}
+	depth := pathDepth(parentPath) + height
+	if depth > l.options.MaxDepth {
+		return &FolderLimitError{Limit: FolderLimitDepth, Max: l.options.MaxDepth, Actual: int64(depth)}
+	}
+	return nil
+}
+
+// CheckChildren 检查向文件夹添加adding个子项后的子项数，parentID为nil表示根目录
+func (l *folderLimiter) CheckChildren(ctx context.Context, userID uint, parentID *uint, adding int) error {
+	count, err := l.folderRepo.CountChildren(ctx, userID, parentID)
+	if err != nil {
+		return fmt.Errorf("统计文件夹子项失败: %w", err)
+	}
+	if total := count + int64(adding); total > int64(l.options.MaxChildren) {
+		return &FolderLimitError{Limit: FolderLimitChildren, Max: l.options.MaxChildren, Actual: total}
+	}
+	return nil
+}
+
+// Report 列出层级或子项数达到上限ratio比例的文件夹，ratio不在(0,1]时使用配置的比例
+func (l *folderLimiter) Report(ctx context.Context, ratio float64, limit int) (*FolderLimitReport, error) {
+	if ratio <= 0 || ratio > 1 {
+		ratio = l.options.WarnRatio
+	}
+	if limit <= 0 {
+		limit = DefaultFolderReportLimit
+	}
+	if limit > MaxFolderReportLimit {
+		limit = MaxFolderReportLimit
+	}
+	report := &FolderLimitReport{
+		MaxDepth:       l.options.MaxDepth,
+		MaxChildren:    l.options.MaxChildren,
+		Ratio:          ratio,
+		DeepFolders:    []FolderUsage{},
+		CrowdedFolders: []FolderUsage{},
+	}
+
+	minDepth := int(math.Ceil(float64(l.options.MaxDepth) * ratio))
+	folders, err := l.folderRepo.ListDeepFolders(ctx, max(minDepth, 1), limit)
+	if err != nil {
+		return nil, fmt.Errorf("查询层级过深的文件夹失败: %w", err)
+	}
+	for _, folder := range folders {
+		id := folder.ID
+		depth := pathDepth(folder.Path) + 1
+		report.DeepFolders = append(report.DeepFolders, FolderUsage{
+			UserID:   folder.UserID,
+			FolderID: &id,
+			Path:     folder.GetFullPath(),
+			Depth:    depth,
+			Usage:    float64(depth) / float64(l.options.MaxDepth),
+		})
+	}
+
+	minChildren := int64(math.Ceil(float64(l.options.MaxChildren) * ratio))
+	counts, err := l.folderRepo.ListCrowdedFolders(ctx, max(minChildren, 1), limit)
+	if err != nil {
+		return nil, fmt.Errorf("查询子项过多的文件夹失败: %w", err)
+	}
+	for _, count := range counts {
+		folderPath := "/"
+		if count.ParentID != nil {
+			folder, err := l.fileRepo.GetByID(ctx, *count.ParentID)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				// 文件夹已移入回收站，其子项随之软删除，不再计入
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("获取文件夹失败: %w", err)
+			}
+			folderPath = folder.GetFullPath()
+		}
+		report.CrowdedFolders = append(report.CrowdedFolders, FolderUsage{
+			UserID:   count.UserID,
+			FolderID: count.ParentID,
+			Path:     folderPath,
+			Children: count.Children,
+			Usage:    float64(count.Children) / float64(l.options.MaxChildren),
+		})
+	}
+
+	l.logger.Debug("Folder limit report generated",
+		zap.Float64("ratio", ratio),
+		zap.Int("deep_folders", len(report.DeepFolders)),
+		zap.Int("crowded_folders", len(report.CrowdedFolders)))
+	return report, nil
+}
+
+// checkFolderLimits 检查向文件夹放入一个项目后的层级和子项数，limiter为nil时不限制
+func checkFolderLimits(ctx context.Context, limiter FolderLimiter, userID uint, parentID *uint, parentPath string, height int) error {
+	if limiter == nil {
+		return nil
+	}
+	if err := limiter.CheckDepth(parentPath, height); err != nil {
+		return err
+	}
+	return limiter.CheckChildren(ctx, userID, parentID, 1)
+}
+
+// pathDepth 路径包含的文件夹层数，根目录为0
+func pathDepth(p string) int {
+	p = strings.Trim(p, "/")
+	if p == "" {
+		return 0
+	}
+	return strings.Count(p, "/") + 1
+}
+
+// subtreeHeight 子树包含的文件夹层数，root为文件时返回0
+//
+// subtree 为 ListSubtree 的结果(含root)，子项的层级按路径相对于root计算
+func subtreeHeight(root *models.File, subtree []*models.File) int {
+	if !root.IsFolder {
+		return 0
+	}
+	base := pathDepth(root.Path)
+	height := 1
+	for _, file := range subtree {
+		if file.IsFolder {
+			height = max(height, pathDepth(file.Path)-base+1)
+		}
+	}
+	return height
+}
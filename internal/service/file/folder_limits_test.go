@@ -0,0 +1,142 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/repository/models"
+)
+
+func TestPathDepth(t *testing.T) {
+	assert.Equal(t, 0, pathDepth(""))
+	assert.Equal(t, 0, pathDepth("/"))
+	assert.Equal(t, 1, pathDepth("/docs"))
+	assert.Equal(t, 3, pathDepth("/docs/sub/deep/"))
+}
+
+func TestSubtreeHeight(t *testing.T) {
+	f := newTreeFixture(t)
+	docs := f.folders.files[1]
+	subtree, err := f.folders.ListSubtree(context.Background(), docs)
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, subtreeHeight(docs, subtree), "/docs 下还有一层 /docs/sub")
+	assert.Equal(t, 1, subtreeHeight(f.folders.files[5], []*models.File{f.folders.files[5]}))
+	assert.Equal(t, 0, subtreeHeight(f.folders.files[2], []*models.File{f.folders.files[2]}), "文件不增加层级")
+}
+
+func TestNewFolderLimiter_Defaults(t *testing.T) {
+	limits := NewFolderLimiter(nil, nil, FolderLimitOptions{MaxDepth: 1000, WarnRatio: 2}, nil).Limits()
+	assert.Equal(t, maxFolderDepth, limits.MaxDepth, "不超过遍历上限")
+	assert.Equal(t, DefaultMaxFolderChildren, limits.MaxChildren)
+	assert.Equal(t, DefaultFolderWarnRatio, limits.WarnRatio)
+}
+
+// requireFolderLimitError 断言错误为指定类型的文件夹限制错误
+func requireFolderLimitError(t *testing.T, err error, limit string, actual int64) {
+	t.Helper()
+	var limitErr *FolderLimitError
+	require.True(t, errors.As(err, &limitErr), "expected FolderLimitError, got %v", err)
+	assert.Equal(t, limit, limitErr.Limit)
+	assert.Equal(t, actual, limitErr.Actual)
+	assert.True(t, pkgErrors.IsPermissionError(err))
+}
+
+func TestTreeService_FolderLimits(t *testing.T) {
+	ctx := context.Background()
+	newLimitedFixture := func(t *testing.T) *treeFixture {
+		f := newTreeFixture(t)
+		f.service.folders = NewFolderLimiter(f.folders, f.folders, FolderLimitOptions{MaxDepth: 2, MaxChildren: 2}, nil)
+		return f
+	}
+
+	t.Run("create folder too deep", func(t *testing.T) {
+		f := newLimitedFixture(t)
+		_, err := f.service.CreateFolder(ctx, 7, uintPtr(3), "drafts")
+		requireFolderLimitError(t, err, FolderLimitDepth, 3)
+	})
+
+	t.Run("create folder in full folder", func(t *testing.T) {
+		f := newLimitedFixture(t)
+		_, err := f.service.CreateFolder(ctx, 7, nil, "music")
+		requireFolderLimitError(t, err, FolderLimitChildren, 3)
+
+		folder, err := f.service.CreateFolder(ctx, 7, uintPtr(5), "2023")
+		require.NoError(t, err)
+		assert.Equal(t, "/archive/2023", folder.GetFullPath())
+	})
+
+	t.Run("move subtree too deep", func(t *testing.T) {
+		f := newLimitedFixture(t)
+		_, err := f.service.Move(ctx, 7, 1, uintPtr(5))
+		requireFolderLimitError(t, err, FolderLimitDepth, 3)
+		assert.Equal(t, "/docs/sub", f.folders.files[4].Path, "拒绝后路径不变")
+	})
+
+	t.Run("move file ignores depth", func(t *testing.T) {
+		f := newLimitedFixture(t)
+		moved, err := f.service.Move(ctx, 7, 4, uintPtr(5))
+		require.NoError(t, err)
+		assert.Equal(t, "/archive/b.txt", moved.GetFullPath())
+
+		_, err = f.service.Move(ctx, 7, 2, nil)
+		requireFolderLimitError(t, err, FolderLimitChildren, 3)
+	})
+
+	t.Run("copy subtree too deep", func(t *testing.T) {
+		f := newLimitedFixture(t)
+		_, err := f.service.Copy(ctx, 7, 1, uintPtr(5))
+		requireFolderLimitError(t, err, FolderLimitDepth, 3)
+		f.accounts.AssertNotCalled(t, "UpdateStorageUsed", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestFolderLimiter_Report(t *testing.T) {
+	f := newTreeFixture(t)
+	limiter := NewFolderLimiter(f.folders, f.folders, FolderLimitOptions{MaxDepth: 2, MaxChildren: 2}, nil)
+
+	report, err := limiter.Report(context.Background(), 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.MaxDepth)
+	assert.Equal(t, 1.0, report.Ratio)
+
+	require.Len(t, report.DeepFolders, 1)
+	assert.Equal(t, "/docs/sub", report.DeepFolders[0].Path)
+	assert.Equal(t, 2, report.DeepFolders[0].Depth)
+	assert.Equal(t, 1.0, report.DeepFolders[0].Usage)
+
+	var paths []string
+	for _, usage := range report.CrowdedFolders {
+		paths = append(paths, usage.Path)
+		assert.Equal(t, int64(2), usage.Children)
+	}
+	assert.ElementsMatch(t, []string{"/", "/docs"}, paths)
+
+	t.Run("default ratio", func(t *testing.T) {
+		report, err := limiter.Report(context.Background(), 0, 10)
+		require.NoError(t, err)
+		assert.Equal(t, DefaultFolderWarnRatio, report.Ratio)
+		assert.Len(t, report.CrowdedFolders, 2, "80%的上限向上取整为2，只有1个子项的/docs/sub不列出")
+	})
+}
+
+func TestChunkedUploadService_InitiateFolderFull(t *testing.T) {
+	folders := &memoryFolders{memoryFileRepository: memoryFileRepository{files: map[uint]*models.File{
+		1: newTestFile(1, 7, nil, "a.bin", false),
+	}}}
+	quota := new(MockQuotaAccountant)
+	limiter := NewFolderLimiter(folders, folders, FolderLimitOptions{MaxChildren: 1}, nil)
+	svc := NewChunkedUploadService(new(MockFileRepository), newMemoryChunkRepository(), quota,
+		nil, nil, limiter, ChunkedUploadOptions{ChunkSize: 4, MaxSize: 1024}, nil, nil, zap.NewNop())
+
+	_, err := svc.Initiate(context.Background(), 7, &ChunkedUploadRequest{Name: "b.bin", Size: 8, Hash: "abc"})
+	requireFolderLimitError(t, err, FolderLimitChildren, 2)
+	quota.AssertNotCalled(t, "Reserve", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,366 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/database"
+	basemodels "cloudpan/internal/pkg/database/models"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	filerepo "cloudpan/internal/repository/file"
+	"cloudpan/internal/repository/models"
+)
+
+// 批量编辑文件标签和属性的限制
+const (
+	MaxBulkMetadataFiles   = 500  // 单次批量编辑最多处理的文件数
+	MaxPropertiesPerFile   = 50   // 单个文件的最大属性数
+	MaxPropertyKeyLength   = 64   // 属性名的最大字符数
+	MaxPropertyValueLength = 1000 // 属性值的最大字符数
+	metadataBatchSize      = 50   // 每个事务处理的文件数
+)
+
+// metadataKeyProperties 文件元数据中保存用户自定义属性的键
+const metadataKeyProperties = "properties"
+
+// MetadataService 批量编辑文件标签和属性的服务接口
+//
+// 同一组修改应用到多个文件：添加和删除标签、设置和删除自定义属性(保存在文件元数据的properties中)。
+// 文件按批在事务中处理，每个文件在各自的保存点中修改，某个文件失败只回滚该文件，不影响同批其他文件；
+// 提交失败时整批文件都返回失败
+//
+// 使用示例：
+//
+//	service := NewMetadataService(tagRepo, fileRepo, database.NewTxManager(db), logger)
+//	outcomes, err := service.BulkUpdate(ctx, []MetadataTarget{{FileID: 1, UserID: 7}}, &BulkMetadataRequest{
+//		AddTags:    []string{"合同"},
+//		Properties: map[string]*string{"项目": &project},
+//	})
+type MetadataService interface {
+	BulkUpdate(ctx context.Context, targets []MetadataTarget, req *BulkMetadataRequest) ([]*MetadataOutcome, error)
+}
+
+// MetadataTarget 批量编辑的文件
+type MetadataTarget struct {
+	FileID uint // 文件ID
+	UserID uint // 访问文件使用的用户ID，获得授权的用户为文件所有者
+}
+
+// BulkMetadataRequest 批量编辑文件标签和属性请求
+type BulkMetadataRequest struct {
+	AddTags    []string           // 添加的标签，文件上已有的同名标签(不区分大小写)跳过
+	TagColor   string             // 新添加标签的颜色，可以为空
+	RemoveTags []string           // 删除的标签(不区分大小写)，文件上没有的标签跳过
+	Properties map[string]*string // 设置的属性，值为nil时删除该属性
+}
+
+// FileMetadata 文件修改后的标签和属性
+type FileMetadata struct {
+	FileID     uint              `json:"file_id"`    // 文件ID
+	Tags       []*FileTag        `json:"tags"`       // 文件上的标签，按添加顺序排列
+	Properties map[string]string `json:"properties"` // 文件的自定义属性
+	Changed    bool              `json:"changed"`    // 是否有修改，文件已是请求的状态时为false
+}
+
+// MetadataOutcome 单个文件的批量编辑结果
+type MetadataOutcome struct {
+	FileID   uint          // 文件ID
+	Metadata *FileMetadata // 成功时修改后的标签和属性
+	Err      error         // 失败原因
+}
+
+// metadataService 批量编辑文件标签和属性服务实现
+type metadataService struct {
+	tags      *tagService
+	tagRepo   filerepo.TagRepository
+	fileRepo  filerepo.FileRepository
+	txManager database.TxManager
+	logger    *zap.Logger
+}
+
+// NewMetadataService 创建批量编辑文件标签和属性服务实例，txManager为nil时不使用事务
+func NewMetadataService(tagRepo filerepo.TagRepository, fileRepo filerepo.FileRepository, txManager database.TxManager, logger *zap.Logger) MetadataService {
+	return &metadataService{
+		tags:      &tagService{tagRepo: tagRepo, fileRepo: fileRepo, logger: logger},
+		tagRepo:   tagRepo,
+		fileRepo:  fileRepo,
+		txManager: txManager,
+		logger:    logger,
+	}
+}
+
+// BulkUpdate 将同一组修改应用到多个文件，按请求顺序返回每个文件的结果；请求本身不合法时返回错误
+func (s *metadataService) BulkUpdate(ctx context.Context, targets []MetadataTarget, req *BulkMetadataRequest) ([]*MetadataOutcome, error) {
+	changes, err := normalizeBulkMetadata(req)
+	if err != nil {
+		return nil, err
+	}
+	if len(targets) > MaxBulkMetadataFiles {
+		return nil, pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "单次最多修改%d个文件", MaxBulkMetadataFiles)
+	}
+
+	outcomes := make([]*MetadataOutcome, 0, len(targets))
+	succeeded := 0
+	for start := 0; start < len(targets); start += metadataBatchSize {
+		batch := targets[start:min(start+metadataBatchSize, len(targets))]
+		results := make([]*MetadataOutcome, len(batch))
+		err := s.inTransaction(ctx, func(ctx context.Context) error {
+			for i, target := range batch {
+				outcome := &MetadataOutcome{FileID: target.FileID}
+				outcome.Err = s.inTransaction(ctx, func(ctx context.Context) error {
+					metadata, err := s.apply(ctx, target, changes)
+					outcome.Metadata = metadata
+					return err
+				})
+				if outcome.Err != nil {
+					outcome.Metadata = nil
+				}
+				results[i] = outcome
+			}
+			return nil
+		})
+		for i, outcome := range results {
+			if outcome == nil {
+				outcome = &MetadataOutcome{FileID: batch[i].FileID}
+				results[i] = outcome
+			}
+			if err != nil && outcome.Err == nil {
+				outcome.Metadata, outcome.Err = nil, fmt.Errorf("保存文件元数据失败: %w", err)
+			}
+			if outcome.Err == nil {
+				succeeded++
+			}
+		}
+		outcomes = append(outcomes, results...)
+	}
+
+	s.logger.Info("File metadata bulk updated",
+		zap.Int("files", len(targets)),
+		zap.Int("succeeded", succeeded),
+		zap.Int("add_tags", len(changes.AddTags)),
+		zap.Int("remove_tags", len(changes.RemoveTags)),
+		zap.Int("properties", len(changes.Properties)))
+	return outcomes, nil
+}
+
+// apply 修改单个文件的标签和属性，先检查限制再写入，检查失败时文件不变
+func (s *metadataService) apply(ctx context.Context, target MetadataTarget, changes *BulkMetadataRequest) (*FileMetadata, error) {
+	file, err := getOwnedActiveFile(ctx, s.fileRepo, target.UserID, target.FileID)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := s.tagRepo.ListByFile(ctx, file.ID, target.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("获取文件标签失败: %w", err)
+	}
+
+	var kept, removed []*models.FileTag
+	for _, tag := range existing {
+		if containsTag(changes.RemoveTags, tag.Tag) {
+			removed = append(removed, tag)
+		} else {
+			kept = append(kept, tag)
+		}
+	}
+	var added []string
+	for _, name := range changes.AddTags {
+		if findTag(kept, name) == nil {
+			added = append(added, name)
+		}
+	}
+	if len(added) > 0 && len(kept)+len(added) > MaxTagsPerFile {
+		return nil, pkgErrors.WrapErrorf(pkgErrors.ErrOperationNotAllowed, "单个文件最多添加%d个标签", MaxTagsPerFile)
+	}
+
+	properties := fileProperties(file)
+	propertiesChanged := false
+	for key, value := range changes.Properties {
+		current, ok := properties[key]
+		switch {
+		case value == nil && ok:
+			delete(properties, key)
+			propertiesChanged = true
+		case value != nil && (!ok || current != *value):
+			properties[key] = *value
+			propertiesChanged = true
+		}
+	}
+	if propertiesChanged && len(properties) > MaxPropertiesPerFile {
+		return nil, pkgErrors.WrapErrorf(pkgErrors.ErrOperationNotAllowed, "单个文件最多设置%d个属性", MaxPropertiesPerFile)
+	}
+
+	for _, tag := range removed {
+		if err := s.tagRepo.Delete(ctx, tag.ID); err != nil {
+			return nil, fmt.Errorf("删除文件标签失败: %w", err)
+		}
+	}
+	for _, name := range added {
+		tag := &models.FileTag{FileID: file.ID, UserID: target.UserID, Tag: name}
+		if changes.TagColor != "" {
+			color := changes.TagColor
+			tag.Color = &color
+		}
+		if err := s.tagRepo.Create(ctx, tag); err != nil {
+			return nil, fmt.Errorf("添加文件标签失败: %w", err)
+		}
+		kept = append(kept, tag)
+	}
+	if len(removed) > 0 || len(added) > 0 {
+		s.tags.syncColumn(ctx, file, func(tags []string) []string {
+			result := make([]string, 0, len(tags)+len(added))
+			for _, tag := range tags {
+				if findTag(removed, tag) == nil {
+					result = append(result, tag)
+				}
+			}
+			return append(result, added...)
+		})
+	}
+	if propertiesChanged {
+		if err := s.fileRepo.UpdateMetadata(ctx, file.ID, withProperties(file.Metadata, properties)); err != nil {
+			return nil, fmt.Errorf("保存文件属性失败: %w", err)
+		}
+	}
+
+	views := make([]*FileTag, len(kept))
+	for i, tag := range kept {
+		views[i] = newFileTagView(tag)
+	}
+	return &FileMetadata{
+		FileID:     file.ID,
+		Tags:       views,
+		Properties: properties,
+		Changed:    len(removed) > 0 || len(added) > 0 || propertiesChanged,
+	}, nil
+}
+
+// inTransaction 在事务中执行fn，已在事务中时创建保存点；未设置事务管理器时直接执行
+func (s *metadataService) inTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if s.txManager == nil {
+		return fn(ctx)
+	}
+	return s.txManager.WithTransaction(ctx, fn)
+}
+
+// normalizeBulkMetadata 校验请求并返回规范化后的修改：标签去除首尾空白并去重，属性名去除首尾空白
+func normalizeBulkMetadata(req *BulkMetadataRequest) (*BulkMetadataRequest, error) {
+	if req == nil || (len(req.AddTags) == 0 && len(req.RemoveTags) == 0 && len(req.Properties) == 0) {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "请指定要修改的标签或属性")
+	}
+	if len(req.AddTags) > MaxTagsPerFile {
+		return nil, pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "单次最多添加%d个标签", MaxTagsPerFile)
+	}
+	if len(req.Properties) > MaxPropertiesPerFile {
+		return nil, pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "单次最多修改%d个属性", MaxPropertiesPerFile)
+	}
+	color := strings.TrimSpace(req.TagColor)
+	if utf8.RuneCountInString(color) > maxTagColorLength {
+		return nil, pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "标签颜色不能超过%d个字符", maxTagColorLength)
+	}
+
+	changes := &BulkMetadataRequest{TagColor: color}
+	var err error
+	if changes.AddTags, err = normalizeTags(req.AddTags); err != nil {
+		return nil, err
+	}
+	if changes.RemoveTags, err = normalizeTags(req.RemoveTags); err != nil {
+		return nil, err
+	}
+	for _, tag := range changes.AddTags {
+		if containsTag(changes.RemoveTags, tag) {
+			return nil, pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "标签%s不能同时添加和删除", tag)
+		}
+	}
+
+	if len(req.Properties) > 0 {
+		changes.Properties = make(map[string]*string, len(req.Properties))
+	}
+	for key, value := range req.Properties {
+		name := strings.TrimSpace(key)
+		if name == "" {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "属性名不能为空")
+		}
+		if utf8.RuneCountInString(name) > MaxPropertyKeyLength {
+			return nil, pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "属性名不能超过%d个字符", MaxPropertyKeyLength)
+		}
+		if strings.ContainsFunc(name, unicode.IsControl) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "属性名不能包含控制字符")
+		}
+		if _, ok := changes.Properties[name]; ok {
+			return nil, pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "属性%s重复", name)
+		}
+		if value != nil && utf8.RuneCountInString(*value) > MaxPropertyValueLength {
+			return nil, pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "属性值不能超过%d个字符", MaxPropertyValueLength)
+		}
+		changes.Properties[name] = value
+	}
+	return changes, nil
+}
+
+// normalizeTags 校验标签并去除重复(不区分大小写)，保持原有顺序
+func normalizeTags(tags []string) ([]string, error) {
+	result := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		name, err := NormalizeTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		if !containsTag(result, name) {
+			result = append(result, name)
+		}
+	}
+	return result, nil
+}
+
+// containsTag 检查标签列表中是否有同名标签，不区分大小写
+func containsTag(tags []string, name string) bool {
+	for _, tag := range tags {
+		if strings.EqualFold(tag, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// fileProperties 返回文件自定义属性的副本，没有属性时返回空map
+func fileProperties(file *models.File) map[string]string {
+	properties := make(map[string]string)
+	if file.Metadata == nil {
+		return properties
+	}
+	raw, _ := (*file.Metadata)[metadataKeyProperties].(map[string]interface{})
+	for key, value := range raw {
+		if text, ok := value.(string); ok {
+			properties[key] = text
+		}
+	}
+	return properties
+}
+
+// withProperties 返回替换自定义属性后的元数据，不修改原元数据；元数据为空时返回nil
+func withProperties(metadata *basemodels.JSONMap, properties map[string]string) *basemodels.JSONMap {
+	updated := basemodels.JSONMap{}
+	if metadata != nil {
+		for key, value := range *metadata {
+			updated[key] = value
+		}
+	}
+	if len(properties) == 0 {
+		delete(updated, metadataKeyProperties)
+	} else {
+		raw := make(map[string]interface{}, len(properties))
+		for key, value := range properties {
+			raw[key] = value
+		}
+		updated[metadataKeyProperties] = raw
+	}
+	if len(updated) == 0 {
+		return nil
+	}
+	return &updated
+}
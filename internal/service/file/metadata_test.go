@@ -0,0 +1,170 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	basemodels "cloudpan/internal/pkg/database/models"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/repository/models"
+)
+
+// txDepthKey 标记ctx已在countingTxManager的事务中
+type txDepthKey struct{}
+
+// countingTxManager 统计事务和保存点次数，commitErr模拟提交失败
+type countingTxManager struct {
+	transactions int
+	savepoints   int
+	commitErr    error
+}
+
+func (m *countingTxManager) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx.Value(txDepthKey{}) != nil {
+		m.savepoints++
+		return fn(ctx)
+	}
+	m.transactions++
+	if err := fn(context.WithValue(ctx, txDepthKey{}, true)); err != nil {
+		return err
+	}
+	return m.commitErr
+}
+
+func newMetadataFixture() (MetadataService, *memoryTags, *memoryFileRepository, *countingTxManager) {
+	files := &memoryFileRepository{files: map[uint]*models.File{
+		1: {Name: "a.pdf", UserID: 7, Status: "active", Metadata: &basemodels.JSONMap{"source": "scanner"}},
+		2: {Name: "b.pdf", UserID: 8, Status: "active"},
+		3: {Name: "c.pdf", UserID: 7, Status: "deleted"},
+		4: {Name: "d.pdf", UserID: 7, Status: "active"},
+	}}
+	for id, f := range files.files {
+		f.ID = id
+	}
+	tags := &memoryTags{}
+	tx := &countingTxManager{}
+	return NewMetadataService(tags, files, tx, zap.NewNop()), tags, files, tx
+}
+
+func TestMetadataService_BulkUpdate(t *testing.T) {
+	service, tags, files, tx := newMetadataFixture()
+	ctx := context.Background()
+	require.NoError(t, tags.Create(ctx, &models.FileTag{FileID: 1, UserID: 7, Tag: "草稿"}))
+	require.NoError(t, tags.Create(ctx, &models.FileTag{FileID: 4, UserID: 7, Tag: "合同"}))
+	draft := "草稿"
+	files.files[1].Tags = &draft
+
+	project := "alpha"
+	targets := []MetadataTarget{{FileID: 1, UserID: 7}, {FileID: 2, UserID: 7}, {FileID: 3, UserID: 7}, {FileID: 4, UserID: 7}}
+	outcomes, err := service.BulkUpdate(ctx, targets, &BulkMetadataRequest{
+		AddTags:    []string{" 合同 ", "合同", "2026"},
+		TagColor:   "#1890ff",
+		RemoveTags: []string{"草稿"},
+		Properties: map[string]*string{" 项目 ": &project},
+	})
+	require.NoError(t, err)
+	require.Len(t, outcomes, 4)
+	assert.Equal(t, 1, tx.transactions, "同一批文件在一个事务中处理")
+	assert.Equal(t, 4, tx.savepoints, "每个文件一个保存点")
+
+	first := outcomes[0]
+	require.NoError(t, first.Err)
+	assert.True(t, first.Metadata.Changed)
+	require.Len(t, first.Metadata.Tags, 2)
+	assert.Equal(t, "合同", first.Metadata.Tags[0].Tag)
+	assert.Equal(t, "#1890ff", *first.Metadata.Tags[0].Color)
+	assert.Equal(t, map[string]string{"项目": "alpha"}, first.Metadata.Properties)
+	assert.Equal(t, "合同,2026", *files.files[1].Tags, "同步tags列")
+	assert.Equal(t, "scanner", (*files.files[1].Metadata)["source"], "保留其他元数据")
+
+	assert.True(t, pkgErrors.IsPermissionError(outcomes[1].Err))
+	assert.Nil(t, outcomes[1].Metadata)
+	assert.ErrorIs(t, outcomes[2].Err, pkgErrors.ErrOperationNotAllowed)
+
+	// 已有的标签跳过，不重复添加
+	require.NoError(t, outcomes[3].Err)
+	require.Len(t, outcomes[3].Metadata.Tags, 2)
+
+	// 已是目标状态时没有修改，删除属性
+	outcomes, err = service.BulkUpdate(ctx, targets[:1], &BulkMetadataRequest{AddTags: []string{"合同"}})
+	require.NoError(t, err)
+	assert.False(t, outcomes[0].Metadata.Changed)
+
+	outcomes, err = service.BulkUpdate(ctx, targets[:1], &BulkMetadataRequest{Properties: map[string]*string{"项目": nil}})
+	require.NoError(t, err)
+	assert.True(t, outcomes[0].Metadata.Changed)
+	assert.Empty(t, outcomes[0].Metadata.Properties)
+	assert.NotContains(t, *files.files[1].Metadata, metadataKeyProperties)
+}
+
+func TestMetadataService_Batches(t *testing.T) {
+	service, tags, files, tx := newMetadataFixture()
+	ctx := context.Background()
+
+	targets := make([]MetadataTarget, 0, 120)
+	for id := uint(100); id < 220; id++ {
+		files.files[id] = &models.File{Name: "f", UserID: 7, Status: "active"}
+		files.files[id].ID = id
+		targets = append(targets, MetadataTarget{FileID: id, UserID: 7})
+	}
+	outcomes, err := service.BulkUpdate(ctx, targets, &BulkMetadataRequest{AddTags: []string{"归档"}})
+	require.NoError(t, err)
+	assert.Equal(t, 3, tx.transactions, "每批50个文件")
+	assert.Len(t, tags.tags, 120)
+	for i, outcome := range outcomes {
+		assert.Equal(t, targets[i].FileID, outcome.FileID, "结果顺序与请求一致")
+	}
+
+	// 提交失败时整批返回失败
+	tx.commitErr = errors.New("deadlock")
+	outcomes, err = service.BulkUpdate(ctx, targets[:2], &BulkMetadataRequest{RemoveTags: []string{"归档"}})
+	require.NoError(t, err)
+	for _, outcome := range outcomes {
+		assert.ErrorContains(t, outcome.Err, "deadlock")
+		assert.Nil(t, outcome.Metadata)
+	}
+}
+
+func TestMetadataService_Limits(t *testing.T) {
+	service, tags, _, _ := newMetadataFixture()
+	ctx := context.Background()
+	targets := []MetadataTarget{{FileID: 1, UserID: 7}}
+
+	for i := 0; i < MaxTagsPerFile; i++ {
+		require.NoError(t, tags.Create(ctx, &models.FileTag{FileID: 1, UserID: 7, Tag: strings.Repeat("t", i+1)}))
+	}
+	outcomes, err := service.BulkUpdate(ctx, targets, &BulkMetadataRequest{AddTags: []string{"新标签"}})
+	require.NoError(t, err)
+	assert.ErrorIs(t, outcomes[0].Err, pkgErrors.ErrOperationNotAllowed)
+	assert.Len(t, tags.tags, MaxTagsPerFile, "超出限制时不修改")
+
+	// 同时删除标签时可以添加
+	outcomes, err = service.BulkUpdate(ctx, targets, &BulkMetadataRequest{AddTags: []string{"新标签"}, RemoveTags: []string{"t"}})
+	require.NoError(t, err)
+	require.NoError(t, outcomes[0].Err)
+
+	value := "v"
+	longValue := strings.Repeat("v", MaxPropertyValueLength+1)
+	invalid := []*BulkMetadataRequest{
+		nil,
+		{},
+		{AddTags: []string{"合同"}, RemoveTags: []string{"合同"}},
+		{AddTags: []string{"a,b"}},
+		{Properties: map[string]*string{" ": &value}},
+		{Properties: map[string]*string{strings.Repeat("k", MaxPropertyKeyLength+1): &value}},
+		{Properties: map[string]*string{"k": &longValue}},
+		{Properties: map[string]*string{"k": &value, " k": &value}},
+	}
+	for _, req := range invalid {
+		_, err := service.BulkUpdate(ctx, targets, req)
+		assert.Error(t, err, "%+v", req)
+	}
+	_, err = service.BulkUpdate(ctx, make([]MetadataTarget, MaxBulkMetadataFiles+1), &BulkMetadataRequest{AddTags: []string{"a"}})
+	assert.ErrorIs(t, err, pkgErrors.ErrInvalidInput)
+}
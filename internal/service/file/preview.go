@@ -0,0 +1,482 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/config"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/storage"
+	"cloudpan/internal/pkg/thumbnail"
+	filerepo "cloudpan/internal/repository/file"
+	"cloudpan/internal/repository/models"
+	"cloudpan/internal/service/jobs"
+)
+
+// 预览尺寸
+const (
+	PreviewKindThumbnail = "thumbnail" // 缩略图，用于文件列表
+	PreviewKindPreview   = "preview"   // 预览图，用于预览页
+)
+
+// 预览默认值
+const (
+	DefaultThumbnailSize        = 256
+	DefaultPreviewSize          = 1024
+	DefaultPreviewMaxSourceSize = 50 << 20
+
+	// previewPrefix 预览图存储路径前缀
+	previewPrefix = "previews"
+	// previewMimeType 预览图统一输出为JPEG
+	previewMimeType = "image/jpeg"
+	// pdfMimeType PDF文件类型
+	pdfMimeType = "application/pdf"
+)
+
+// ErrPreviewPending 预览尚未生成，已安排后台任务，稍后重试
+var ErrPreviewPending = errors.New("preview pending")
+
+// PreviewService 文件预览服务接口
+//
+// 为图片(JPEG/PNG/GIF)和配置了渲染命令时的PDF首页生成缩略图和预览图：
+// 1. 上传完成后安排后台任务(jobs.TypeThumbnail工作池)生成两种尺寸的JPEG，存入文件存储后端
+// 2. 生成完成后记录文件的 thumbnail_url 和 preview_url，预览状态(preview_status)随之更新为ready或failed
+// 3. 读取预览时校验访问权限(与下载相同)，尚未生成时安排交互优先级任务并返回 ErrPreviewPending
+//
+// 预览图按文件内容版本(哈希，没有哈希时为修改时间)存储，文件内容更新后自动重新生成；
+// 源文件无法解码时记录失败标记，同一版本不再重试
+//
+// 使用示例：
+//
+//	service := NewPreviewService(fileRepo, store, jobs.Default(), PreviewOptionsFromConfig(cfg.Storage.Preview), logger)
+//	service.Schedule(ctx, file, jobs.PriorityInteractive)
+//	preview, err := service.Open(ctx, userID, fileID, PreviewKindThumbnail)
+//	defer preview.Content.Close()
+type PreviewService interface {
+	PreviewScheduler
+	Generate(ctx context.Context, fileID uint) error
+	Open(ctx context.Context, userID, fileID uint, kind string) (*FilePreview, error)
+}
+
+// PreviewScheduler 安排预览生成任务，由上传处理器在上传完成后调用
+type PreviewScheduler interface {
+	Schedule(ctx context.Context, file *models.File, priority jobs.Priority)
+}
+
+// JobEnqueuer 后台任务提交，由 jobs.Queue 实现
+type JobEnqueuer interface {
+	Enqueue(job jobs.Job) error
+}
+
+// PreviewOptions 预览选项
+type PreviewOptions struct {
+	ThumbnailSize int                    // 缩略图最长边(像素)
+	PreviewSize   int                    // 预览图最长边(像素)
+	MaxSourceSize int64                  // 源文件大小上限(字节)
+	MaxPixels     int                    // 允许解码的最大像素数
+	PDFRenderer   *thumbnail.PDFRenderer // PDF首页渲染，为nil时不生成PDF预览
+}
+
+// PreviewOptionsFromConfig 从预览配置生成选项
+func PreviewOptionsFromConfig(cfg config.PreviewConfig) PreviewOptions {
+	options := PreviewOptions{
+		ThumbnailSize: cfg.ThumbnailSize,
+		PreviewSize:   cfg.PreviewSize,
+		MaxSourceSize: cfg.MaxSourceSize,
+		MaxPixels:     cfg.MaxPixels,
+	}
+	if cfg.PDFCommand != "" {
+		options.PDFRenderer = &thumbnail.PDFRenderer{Command: cfg.PDFCommand, Timeout: cfg.PDFTimeout}
+	}
+	return options
+}
+
+// withDefaults 填充未配置的选项
+func (o PreviewOptions) withDefaults() PreviewOptions {
+	if o.ThumbnailSize <= 0 {
+		o.ThumbnailSize = DefaultThumbnailSize
+	}
+	if o.PreviewSize <= 0 {
+		o.PreviewSize = DefaultPreviewSize
+	}
+	if o.MaxSourceSize <= 0 {
+		o.MaxSourceSize = DefaultPreviewMaxSourceSize
+	}
+	if o.MaxPixels <= 0 {
+		o.MaxPixels = thumbnail.DefaultMaxPixels
+	}
+	return o
+}
+
+// FilePreview 预览图内容
+type FilePreview struct {
+	FileID   uint          // 文件ID
+	Kind     string        // 预览尺寸
+	MimeType string        // 内容类型，固定为image/jpeg
+	Size     int64         // 内容大小
+	ModTime  time.Time     // 文件修改时间
+	ETag     string        // 实体标签(带引号)，由文件内容版本和尺寸组成
+	Content  io.ReadCloser // 预览图内容，调用方负责关闭
+}
+
+// previewService 文件预览服务实现
+type previewService struct {
+	fileRepo filerepo.FileRepository
+	store    storage.Storage
+	queue    JobEnqueuer
+	options  PreviewOptions
+	logger   *zap.Logger
+	now      func() time.Time
+
+	// pending 已提交尚未执行完的文件ID，避免重复排队
+	pending sync.Map
+	// locks 按预览目录加锁，避免同一版本被并发生成
+	locks sync.Map
+}
+
+// NewPreviewService 创建文件预览服务
+//
+// queue 可以为nil(如任务队列未启动)，此时不安排后台任务，尚未生成的预览一直返回 ErrPreviewPending
+func NewPreviewService(fileRepo filerepo.FileRepository, store storage.Storage, queue JobEnqueuer, options PreviewOptions, logger *zap.Logger) PreviewService {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &previewService{
+		fileRepo: fileRepo,
+		store:    store,
+		queue:    queue,
+		options:  options.withDefaults(),
+		logger:   logger,
+		now:      time.Now,
+	}
+}
+
+// Schedule 为可预览的文件安排生成任务并将预览状态标记为pending，不可预览的文件忽略
+//
+// 任务排队失败只记录日志并恢复原状态，用户打开预览时会再次安排
+func (s *previewService) Schedule(ctx context.Context, file *models.File, priority jobs.Priority) {
+	if s.queue == nil || file == nil || !s.previewable(file) {
+		return
+	}
+	if _, loaded := s.pending.LoadOrStore(file.ID, struct{}{}); loaded {
+		return
+	}
+
+	// 先标记状态再排队，避免任务先完成后被覆盖为pending
+	fileID := file.ID
+	previous := file.PreviewStatus
+	if err := s.updateStatus(ctx, file, models.PreviewStatusPending); err != nil {
+		s.logger.Warn("Failed to mark preview pending",
+			zap.Uint("file_id", fileID),
+			zap.Error(err))
+	}
+	err := s.queue.Enqueue(jobs.Job{
+		Type:     jobs.TypeThumbnail,
+		Priority: priority,
+		Key:      "file:" + strconv.FormatUint(uint64(fileID), 10),
+		Run: func(ctx context.Context) error {
+			defer s.pending.Delete(fileID)
+			return s.Generate(ctx, fileID)
+		},
+	})
+	if err != nil {
+		s.pending.Delete(fileID)
+		s.logger.Warn("Failed to schedule preview generation",
+			zap.Uint("file_id", fileID),
+			zap.Stringer("priority", priority),
+			zap.Error(err))
+		if err := s.updateStatus(ctx, file, previous); err != nil {
+			s.logger.Warn("Failed to restore preview status",
+				zap.Uint("file_id", fileID),
+				zap.Error(err))
+		}
+	}
+}
+
+// Generate 生成文件当前版本的缩略图和预览图并记录预览地址
+//
+// 文件已删除、不可预览或已生成时直接返回；源文件无法解码时记录失败标记并返回nil
+func (s *previewService) Generate(ctx context.Context, fileID uint) error {
+	file, err := s.fileRepo.GetByID(ctx, fileID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return fmt.Errorf("获取文件失败: %w", err)
+	}
+	if !file.IsActive() || !s.previewable(file) || file.StorageType != s.store.Type() {
+		return nil
+	}
+	switch file.ArchiveState(s.now()) {
+	case models.ArchiveStateArchived, models.ArchiveStateRestoring:
+		return nil
+	}
+
+	dir := previewDir(file)
+	lock, _ := s.locks.LoadOrStore(dir, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	state, err := s.state(ctx, dir)
+	if err != nil {
+		return err
+	}
+	switch state {
+	case previewFailed:
+		return s.updateStatus(ctx, file, models.PreviewStatusFailed)
+	case previewMissing:
+		if err := s.render(ctx, file, dir); err != nil {
+			var failure *previewFailure
+			if !errors.As(err, &failure) {
+				return err
+			}
+			s.logger.Warn("Preview generation failed, source cannot be rendered",
+				zap.Uint("file_id", file.ID),
+				zap.Error(failure.err))
+			if err := s.store.Put(ctx, path.Join(dir, ".failed"), bytes.NewReader(nil), 0); err != nil {
+				return fmt.Errorf("记录预览失败标记失败: %w", err)
+			}
+			return s.updateStatus(ctx, file, models.PreviewStatusFailed)
+		}
+	}
+
+	thumbnailURL, previewURL := previewURLs(file.ID)
+	if file.ThumbnailURL == nil || *file.ThumbnailURL != thumbnailURL || file.PreviewURL == nil || *file.PreviewURL != previewURL {
+		if err := s.fileRepo.UpdatePreviewURLs(ctx, file.ID, &thumbnailURL, &previewURL); err != nil {
+			return fmt.Errorf("记录预览地址失败: %w", err)
+		}
+	}
+	return s.updateStatus(ctx, file, models.PreviewStatusReady)
+}
+
+// updateStatus 更新文件的预览状态，状态未变化时不写数据库
+func (s *previewService) updateStatus(ctx context.Context, file *models.File, status string) error {
+	if file.PreviewStatus == status {
+		return nil
+	}
+	if err := s.fileRepo.UpdatePreviewStatus(ctx, file.ID, status); err != nil {
+		return fmt.Errorf("记录预览状态失败: %w", err)
+	}
+	file.PreviewStatus = status
+	return nil
+}
+
+// Open 校验访问权限并打开预览图，尚未生成时安排任务并返回 ErrPreviewPending
+//
+// 访问权限与下载相同：文件所有者，或访问级别为public的文件对所有登录用户开放
+func (s *previewService) Open(ctx context.Context, userID, fileID uint, kind string) (*FilePreview, error) {
+	if userID == 0 || fileID == 0 {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "用户ID和文件ID不能为空")
+	}
+	if kind != PreviewKindThumbnail && kind != PreviewKindPreview {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "预览尺寸只能是thumbnail或preview")
+	}
+
+	file, err := s.fileRepo.GetByID(ctx, fileID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "文件不存在")
+		}
+		return nil, fmt.Errorf("获取文件失败: %w", err)
+	}
+	if !file.IsActive() {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "文件不存在")
+	}
+	if file.UserID != userID && file.AccessLevel != models.AccessLevelPublic {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, "无权查看该文件")
+	}
+	if !s.previewable(file) {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "该文件没有预览")
+	}
+	if file.StorageType != s.store.Type() {
+		return nil, pkgErrors.WrapErrorf(pkgErrors.ErrOperationNotAllowed, "文件所在的存储(%s)当前不可用", file.StorageType)
+	}
+
+	dir := previewDir(file)
+	state, err := s.state(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	switch state {
+	case previewFailed:
+		return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "该文件无法生成预览")
+	case previewMissing:
+		// 预览图独立于源文件存储，已生成的预览在归档后仍可读取，但归档后无法再生成
+		switch file.ArchiveState(s.now()) {
+		case models.ArchiveStateArchived, models.ArchiveStateRestoring:
+			return nil, pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "文件已归档，请先恢复后再预览")
+		}
+		s.Schedule(ctx, file, jobs.PriorityInteractive)
+		return nil, ErrPreviewPending
+	}
+
+	objectPath := path.Join(dir, kind+".jpg")
+	info, err := s.store.Stat(ctx, objectPath)
+	if err != nil {
+		if storage.IsNotFound(err) {
+			s.logger.Error("Preview object missing from storage",
+				zap.Uint("file_id", file.ID),
+				zap.String("path", objectPath))
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "预览不存在")
+		}
+		return nil, fmt.Errorf("读取预览信息失败: %w", err)
+	}
+	content, err := s.store.Open(ctx, objectPath)
+	if err != nil {
+		if storage.IsNotFound(err) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "预览不存在")
+		}
+		return nil, fmt.Errorf("打开预览失败: %w", err)
+	}
+
+	return &FilePreview{
+		FileID:   file.ID,
+		Kind:     kind,
+		MimeType: previewMimeType,
+		Size:     info.Size,
+		ModTime:  file.UpdatedAt,
+		ETag:     `"` + contentVersion(file) + "-" + kind + `"`,
+		Content:  content,
+	}, nil
+}
+
+// previewable 检查文件是否可以生成预览
+func (s *previewService) previewable(file *models.File) bool {
+	if file.IsFolder || file.StoragePath == nil || file.MimeType == nil || file.Size > s.options.MaxSourceSize {
+		return false
+	}
+	if thumbnail.Supported(*file.MimeType) {
+		return true
+	}
+	return *file.MimeType == pdfMimeType && s.options.PDFRenderer.Enabled()
+}
+
+// previewState 预览目录的生成状态
+type previewState int
+
+const (
+	previewMissing previewState = iota // 尚未生成
+	previewReady                       // 已生成
+	previewFailed                      // 源文件无法渲染
+)
+
+// state 根据标记文件判断预览目录的生成状态
+func (s *previewService) state(ctx context.Context, dir string) (previewState, error) {
+	ready, err := s.store.Exists(ctx, path.Join(dir, ".ready"))
+	if err != nil {
+		return previewMissing, fmt.Errorf("检查预览失败: %w", err)
+	}
+	if ready {
+		return previewReady, nil
+	}
+	failed, err := s.store.Exists(ctx, path.Join(dir, ".failed"))
+	if err != nil {
+		return previewMissing, fmt.Errorf("检查预览失败: %w", err)
+	}
+	if failed {
+		return previewFailed, nil
+	}
+	return previewMissing, nil
+}
+
+// previewFailure 源文件无法渲染，重试也不会成功
+type previewFailure struct {
+	err error
+}
+
+// Error 实现error接口
+func (e *previewFailure) Error() string {
+	return e.err.Error()
+}
+
+// render 解码源文件并写入两种尺寸的预览图，全部写入后才写入完成标记
+func (s *previewService) render(ctx context.Context, file *models.File, dir string) error {
+	source, err := s.store.Open(ctx, *file.StoragePath)
+	if err != nil {
+		if storage.IsNotFound(err) {
+			return &previewFailure{err: fmt.Errorf("文件内容不存在: %w", err)}
+		}
+		return fmt.Errorf("打开文件失败: %w", err)
+	}
+	defer source.Close()
+
+	var img image.Image
+	if *file.MimeType == pdfMimeType {
+		img, err = s.options.PDFRenderer.RenderFirstPage(ctx, source, s.options.PreviewSize, s.options.MaxPixels)
+	} else {
+		img, err = thumbnail.Decode(source, s.options.MaxPixels)
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return &previewFailure{err: err}
+	}
+
+	// 缩略图由预览图再次缩小得到，避免重复处理原图
+	preview := thumbnail.Resize(img, s.options.PreviewSize)
+	images := map[string]image.Image{
+		PreviewKindPreview:   preview,
+		PreviewKindThumbnail: thumbnail.Resize(preview, s.options.ThumbnailSize),
+	}
+	for kind, img := range images {
+		if err := s.write(ctx, path.Join(dir, kind+".jpg"), img); err != nil {
+			return err
+		}
+	}
+	if err := s.store.Put(ctx, path.Join(dir, ".ready"), bytes.NewReader(nil), 0); err != nil {
+		return fmt.Errorf("保存预览失败: %w", err)
+	}
+
+	s.logger.Info("Generated file preview",
+		zap.Uint("file_id", file.ID),
+		zap.String("path", dir))
+	return nil
+}
+
+// write 编码并保存一张预览图
+func (s *previewService) write(ctx context.Context, objectPath string, img image.Image) error {
+	writer, err := s.store.Create(ctx, objectPath)
+	if err != nil {
+		return fmt.Errorf("创建预览失败: %w", err)
+	}
+	if err := thumbnail.Encode(writer, img, 0); err != nil {
+		_ = writer.Abort()
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		_ = writer.Abort()
+		return fmt.Errorf("保存预览失败: %w", err)
+	}
+	return nil
+}
+
+// previewDir 文件当前内容版本的预览目录
+func previewDir(file *models.File) string {
+	return path.Join(previewPrefix, file.UUID, contentVersion(file))
+}
+
+// contentVersion 文件内容版本，优先使用哈希，没有哈希时使用修改时间
+func contentVersion(file *models.File) string {
+	if file.Hash != nil && *file.Hash != "" {
+		return *file.Hash
+	}
+	return strconv.FormatInt(file.UpdatedAt.UnixNano(), 10)
+}
+
+// previewURLs 文件缩略图和预览图的访问地址
+func previewURLs(fileID uint) (string, string) {
+	base := "/api/v1/files/" + strconv.FormatUint(uint64(fileID), 10) + "/preview"
+	return base + "?size=" + PreviewKindThumbnail, base
+}
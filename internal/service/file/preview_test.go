@@ -0,0 +1,239 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/storage"
+	"cloudpan/internal/repository/models"
+	"cloudpan/internal/service/jobs"
+)
+
+// recordingQueue 记录提交的任务，由测试手动执行
+type recordingQueue struct {
+	jobs []jobs.Job
+	err  error
+}
+
+func (q *recordingQueue) Enqueue(job jobs.Job) error {
+	if q.err != nil {
+		return q.err
+	}
+	q.jobs = append(q.jobs, job)
+	return nil
+}
+
+// encodeTestPNG 生成指定尺寸的PNG
+func encodeTestPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 0x80, A: 0xff})
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func newPreviewFixture(t *testing.T, content []byte, mimeType string) (*previewService, *memoryFileRepository, *recordingQueue, *models.File) {
+	t.Helper()
+	store, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, store.Put(context.Background(), "files/5", bytes.NewReader(content), int64(len(content))))
+
+	storagePath := "files/5"
+	hash := "0f343b0931126a20f133d67c2b018a3b"
+	file := newTestFile(5, 7, nil, "photo.png", false)
+	file.UUID = "3f6c2a1e-uuid"
+	file.StoragePath = &storagePath
+	file.StorageType = storage.StorageTypeLocal
+	file.Hash = &hash
+	file.MimeType = &mimeType
+	file.Size = int64(len(content))
+	file.Status = "active"
+
+	repo := &memoryFileRepository{files: map[uint]*models.File{5: file}}
+	queue := &recordingQueue{}
+	options := PreviewOptions{ThumbnailSize: 32, PreviewSize: 100}
+	service := NewPreviewService(repo, store, queue, options, zap.NewNop()).(*previewService)
+	return service, repo, queue, file
+}
+
+func TestPreviewService_Generate(t *testing.T) {
+	ctx := context.Background()
+	service, _, _, file := newPreviewFixture(t, encodeTestPNG(t, 400, 200), "image/png")
+
+	require.NoError(t, service.Generate(ctx, 5))
+
+	require.NotNil(t, file.ThumbnailURL)
+	require.NotNil(t, file.PreviewURL)
+	assert.Equal(t, "/api/v1/files/5/preview?size=thumbnail", *file.ThumbnailURL)
+	assert.Equal(t, "/api/v1/files/5/preview", *file.PreviewURL)
+	assert.Equal(t, models.PreviewStatusReady, file.PreviewStatus)
+
+	for kind, want := range map[string]image.Rectangle{
+		PreviewKindThumbnail: image.Rect(0, 0, 32, 16),
+		PreviewKindPreview:   image.Rect(0, 0, 100, 50),
+	} {
+		preview, err := service.Open(ctx, 7, 5, kind)
+		require.NoError(t, err)
+		img, err := jpeg.Decode(preview.Content)
+		require.NoError(t, err)
+		preview.Content.Close()
+
+		assert.Equal(t, want, img.Bounds(), kind)
+		assert.Equal(t, "image/jpeg", preview.MimeType)
+		assert.Equal(t, `"0f343b0931126a20f133d67c2b018a3b-`+kind+`"`, preview.ETag)
+	}
+}
+
+func TestPreviewService_GenerateFailureIsRemembered(t *testing.T) {
+	ctx := context.Background()
+	service, _, queue, file := newPreviewFixture(t, []byte("not really a png"), "image/png")
+
+	require.NoError(t, service.Generate(ctx, 5))
+	assert.Nil(t, file.ThumbnailURL)
+	assert.Equal(t, models.PreviewStatusFailed, file.PreviewStatus)
+
+	_, err := service.Open(ctx, 7, 5, PreviewKindThumbnail)
+	assert.True(t, pkgErrors.IsNotFoundError(err))
+	assert.Empty(t, queue.jobs, "failed versions should not be retried")
+}
+
+func TestPreviewService_OpenSchedulesPendingPreview(t *testing.T) {
+	ctx := context.Background()
+	service, _, queue, file := newPreviewFixture(t, encodeTestPNG(t, 50, 50), "image/png")
+
+	_, err := service.Open(ctx, 7, 5, PreviewKindPreview)
+	assert.ErrorIs(t, err, ErrPreviewPending)
+	_, err = service.Open(ctx, 7, 5, PreviewKindThumbnail)
+	assert.ErrorIs(t, err, ErrPreviewPending)
+
+	require.Len(t, queue.jobs, 1, "pending file should be queued once")
+	assert.Equal(t, models.PreviewStatusPending, file.PreviewStatus)
+	job := queue.jobs[0]
+	assert.Equal(t, jobs.TypeThumbnail, job.Type)
+	assert.Equal(t, jobs.PriorityInteractive, job.Priority)
+	assert.Equal(t, "file:5", job.Key)
+
+	require.NoError(t, job.Run(ctx))
+	assert.NotNil(t, file.ThumbnailURL)
+
+	preview, err := service.Open(ctx, 7, 5, PreviewKindPreview)
+	require.NoError(t, err)
+	preview.Content.Close()
+}
+
+func TestPreviewService_Schedule(t *testing.T) {
+	t.Run("ignores unsupported files", func(t *testing.T) {
+		service, _, queue, file := newPreviewFixture(t, []byte("hello"), "text/plain")
+
+		service.Schedule(context.Background(), file, jobs.PriorityBatch)
+		assert.Empty(t, queue.jobs)
+		assert.Empty(t, file.PreviewStatus)
+	})
+
+	t.Run("ignores files over source size limit", func(t *testing.T) {
+		service, _, queue, file := newPreviewFixture(t, encodeTestPNG(t, 10, 10), "image/png")
+		file.Size = DefaultPreviewMaxSourceSize + 1
+
+		service.Schedule(context.Background(), file, jobs.PriorityBatch)
+		assert.Empty(t, queue.jobs)
+	})
+
+	t.Run("pdf requires renderer", func(t *testing.T) {
+		service, _, queue, file := newPreviewFixture(t, []byte("%PDF-1.7"), "application/pdf")
+
+		service.Schedule(context.Background(), file, jobs.PriorityBatch)
+		assert.Empty(t, queue.jobs)
+	})
+
+	t.Run("queue full can be retried", func(t *testing.T) {
+		service, _, queue, file := newPreviewFixture(t, encodeTestPNG(t, 10, 10), "image/png")
+		queue.err = jobs.ErrQueueFull
+
+		service.Schedule(context.Background(), file, jobs.PriorityInteractive)
+		assert.Empty(t, file.PreviewStatus, "status restored when queueing fails")
+		queue.err = nil
+		service.Schedule(context.Background(), file, jobs.PriorityInteractive)
+		assert.Len(t, queue.jobs, 1)
+		assert.Equal(t, models.PreviewStatusPending, file.PreviewStatus)
+	})
+}
+
+func TestPreviewService_OpenErrors(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		setup  func(f *models.File)
+		userID uint
+		kind   string
+		check  func(error) bool
+	}{
+		{"invalid kind", nil, 7, "huge", pkgErrors.IsValidationError},
+		{"other user's private file", nil, 8, PreviewKindThumbnail, pkgErrors.IsPermissionError},
+		{"deleted file", func(f *models.File) { f.Status = "deleted" }, 7, PreviewKindThumbnail, pkgErrors.IsNotFoundError},
+		{"folder", func(f *models.File) { f.IsFolder = true }, 7, PreviewKindThumbnail, pkgErrors.IsNotFoundError},
+		{"unsupported type", func(f *models.File) { f.MimeType = strPtr("text/plain") }, 7, PreviewKindThumbnail, pkgErrors.IsNotFoundError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _, _, file := newPreviewFixture(t, encodeTestPNG(t, 10, 10), "image/png")
+			if tt.setup != nil {
+				tt.setup(file)
+			}
+			_, err := service.Open(ctx, tt.userID, 5, tt.kind)
+			require.Error(t, err)
+			assert.True(t, tt.check(err), "unexpected error: %v", err)
+		})
+	}
+
+	t.Run("public file is visible to other users", func(t *testing.T) {
+		service, _, _, file := newPreviewFixture(t, encodeTestPNG(t, 10, 10), "image/png")
+		file.AccessLevel = models.AccessLevelPublic
+
+		_, err := service.Open(ctx, 8, 5, PreviewKindThumbnail)
+		assert.ErrorIs(t, err, ErrPreviewPending)
+	})
+}
+
+func TestPreviewService_RegeneratesAfterContentChange(t *testing.T) {
+	ctx := context.Background()
+	service, _, _, file := newPreviewFixture(t, encodeTestPNG(t, 60, 60), "image/png")
+	require.NoError(t, service.Generate(ctx, 5))
+
+	newContent := encodeTestPNG(t, 20, 40)
+	require.NoError(t, service.store.Put(ctx, "files/5", bytes.NewReader(newContent), int64(len(newContent))))
+	file.Hash = strPtr("a0c1d2e3f4a5b6c7d8e9f0a1b2c3d4e5")
+
+	_, err := service.Open(ctx, 7, 5, PreviewKindPreview)
+	assert.ErrorIs(t, err, ErrPreviewPending)
+
+	require.NoError(t, service.Generate(ctx, 5))
+	preview, err := service.Open(ctx, 7, 5, PreviewKindPreview)
+	require.NoError(t, err)
+	defer preview.Content.Close()
+	img, err := jpeg.Decode(preview.Content)
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 20, 40), img.Bounds())
+}
+
+func TestPreviewService_GenerateSkipsMissingFile(t *testing.T) {
+	service, repo, _, _ := newPreviewFixture(t, encodeTestPNG(t, 10, 10), "image/png")
+	delete(repo.files, 5)
+
+	assert.NoError(t, service.Generate(context.Background(), 5))
+}
@@ -0,0 +1,200 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/antivirus"
+	"cloudpan/internal/pkg/config"
+	"cloudpan/internal/pkg/storage"
+	filerepo "cloudpan/internal/repository/file"
+	"cloudpan/internal/repository/models"
+	"cloudpan/internal/service/jobs"
+)
+
+// DefaultScanMaxFileSize 默认扫描的文件大小上限(100MB)，与clamd默认的StreamMaxLength一致
+const DefaultScanMaxFileSize int64 = 100 << 20
+
+// ScanService 上传文件病毒扫描服务接口
+//
+// 上传完成后安排后台任务(jobs.TypeScan工作池)将文件内容以流的形式发送给clamd扫描，
+// 结果记录在文件的 scan_status 中(models.ScanStatusClean等)，通过文件元数据的processing对象返回给客户端。
+// 未配置扫描器、任务队列未启动或文件超过大小上限时直接标记为skipped；检出病毒只记录状态和日志，不删除文件
+//
+// 使用示例：
+//
+//	service := NewScanService(fileRepo, store, &antivirus.ClamAV{Address: socket}, jobs.Default(), ScanOptions{}, logger)
+//	service.Schedule(ctx, file)
+type ScanService interface {
+	ScanScheduler
+	Scan(ctx context.Context, fileID uint) error
+}
+
+// ScanScheduler 安排病毒扫描任务，由上传处理器在上传完成后调用
+type ScanScheduler interface {
+	Schedule(ctx context.Context, file *models.File)
+}
+
+// ScanOptions 病毒扫描选项
+type ScanOptions struct {
+	MaxFileSize int64 // 扫描的文件大小上限(字节)
+}
+
+// ScanOptionsFromConfig 从病毒扫描配置生成选项
+func ScanOptionsFromConfig(cfg config.AntivirusConfig) ScanOptions {
+	return ScanOptions{MaxFileSize: cfg.MaxFileSize}
+}
+
+// scanService 病毒扫描服务实现
+type scanService struct {
+	fileRepo filerepo.FileRepository
+	store    storage.Storage
+	scanner  antivirus.Scanner
+	queue    JobEnqueuer
+	options  ScanOptions
+	logger   *zap.Logger
+
+	// pending 已提交尚未执行完的文件ID，避免重复排队
+	pending sync.Map
+}
+
+// NewScanService 创建病毒扫描服务
+//
+// scanner 可以为nil(未启用病毒扫描)，queue 可以为nil(任务队列未启动)，此时上传的文件都标记为skipped
+func NewScanService(fileRepo filerepo.FileRepository, store storage.Storage, scanner antivirus.Scanner, queue JobEnqueuer, options ScanOptions, logger *zap.Logger) ScanService {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	if options.MaxFileSize <= 0 {
+		options.MaxFileSize = DefaultScanMaxFileSize
+	}
+	return &scanService{
+		fileRepo: fileRepo,
+		store:    store,
+		scanner:  scanner,
+		queue:    queue,
+		options:  options,
+		logger:   logger,
+	}
+}
+
+// Schedule 为上传完成的文件安排扫描任务，无法扫描的文件直接标记为skipped
+//
+// 任务排队失败时标记为failed并记录日志
+func (s *scanService) Schedule(ctx context.Context, file *models.File) {
+	if file == nil || file.IsFolder {
+		return
+	}
+	if s.scanner == nil || s.queue == nil || file.Size > s.options.MaxFileSize {
+		s.setStatus(ctx, file, models.ScanStatusSkipped)
+		return
+	}
+	if _, loaded := s.pending.LoadOrStore(file.ID, struct{}{}); loaded {
+		return
+	}
+
+	s.setStatus(ctx, file, models.ScanStatusPending)
+	fileID := file.ID
+	err := s.queue.Enqueue(jobs.Job{
+		Type:     jobs.TypeScan,
+		Priority: jobs.PriorityInteractive,
+		Key:      "file:" + strconv.FormatUint(uint64(fileID), 10),
+		Run: func(ctx context.Context) error {
+			defer s.pending.Delete(fileID)
+			return s.Scan(ctx, fileID)
+		},
+	})
+	if err != nil {
+		s.pending.Delete(fileID)
+		s.logger.Warn("Failed to schedule virus scan",
+			zap.Uint("file_id", fileID),
+			zap.Error(err))
+		s.setStatus(ctx, file, models.ScanStatusFailed)
+	}
+}
+
+// Scan 扫描文件内容并记录结果
+//
+// 文件已删除或已有扫描结果时直接返回；clamd不可用或文件内容缺失时标记为failed，
+// 文件超过clamd的大小限制时标记为skipped
+func (s *scanService) Scan(ctx context.Context, fileID uint) error {
+	file, err := s.fileRepo.GetByID(ctx, fileID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return fmt.Errorf("获取文件失败: %w", err)
+	}
+	if !file.IsActive() || file.IsFolder || file.ScanStatus != models.ScanStatusPending {
+		return nil
+	}
+	if file.StoragePath == nil || file.StorageType != s.store.Type() || s.scanner == nil {
+		return s.updateStatus(ctx, file, models.ScanStatusSkipped)
+	}
+
+	content, err := s.store.Open(ctx, *file.StoragePath)
+	if err != nil {
+		if storage.IsNotFound(err) {
+			s.logger.Error("Virus scan failed, file content missing",
+				zap.Uint("file_id", file.ID),
+				zap.String("path", *file.StoragePath))
+			return s.updateStatus(ctx, file, models.ScanStatusFailed)
+		}
+		return fmt.Errorf("打开文件失败: %w", err)
+	}
+	defer content.Close()
+
+	result, err := s.scanner.Scan(ctx, content)
+	switch {
+	case errors.Is(err, antivirus.ErrSizeLimitExceeded):
+		s.logger.Info("File exceeds clamd stream limit, scan skipped",
+			zap.Uint("file_id", file.ID),
+			zap.Int64("size", file.Size))
+		return s.updateStatus(ctx, file, models.ScanStatusSkipped)
+	case err != nil:
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		s.logger.Error("Virus scan failed",
+			zap.Uint("file_id", file.ID),
+			zap.Error(err))
+		return s.updateStatus(ctx, file, models.ScanStatusFailed)
+	case result.Infected:
+		s.logger.Warn("Virus detected in uploaded file",
+			zap.Uint("file_id", file.ID),
+			zap.Uint("user_id", file.UserID),
+			zap.String("name", file.Name),
+			zap.String("signature", result.Signature))
+		return s.updateStatus(ctx, file, models.ScanStatusInfected)
+	default:
+		return s.updateStatus(ctx, file, models.ScanStatusClean)
+	}
+}
+
+// setStatus 更新扫描状态，失败只记录日志，用于不返回错误的 Schedule
+func (s *scanService) setStatus(ctx context.Context, file *models.File, status string) {
+	if err := s.updateStatus(ctx, file, status); err != nil {
+		s.logger.Warn("Failed to update scan status",
+			zap.Uint("file_id", file.ID),
+			zap.String("status", status),
+			zap.Error(err))
+	}
+}
+
+// updateStatus 更新文件的扫描状态，状态未变化时不写数据库
+func (s *scanService) updateStatus(ctx context.Context, file *models.File, status string) error {
+	if file.ScanStatus == status {
+		return nil
+	}
+	if err := s.fileRepo.UpdateScanStatus(ctx, file.ID, status); err != nil {
+		return fmt.Errorf("记录扫描状态失败: %w", err)
+	}
+	file.ScanStatus = status
+	return nil
+}
@@ -0,0 +1,140 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/antivirus"
+	"cloudpan/internal/pkg/storage"
+	"cloudpan/internal/repository/models"
+	"cloudpan/internal/service/jobs"
+)
+
+// fakeScanner 内容包含EICAR时报告病毒
+type fakeScanner struct {
+	err     error
+	scanned int
+}
+
+func (s *fakeScanner) Scan(_ context.Context, src io.Reader) (*antivirus.Result, error) {
+	s.scanned++
+	if s.err != nil {
+		return nil, s.err
+	}
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Contains(data, []byte("EICAR")) {
+		return &antivirus.Result{Infected: true, Signature: "Eicar-Test-Signature"}, nil
+	}
+	return &antivirus.Result{}, nil
+}
+
+func newScanFixture(t *testing.T, content string, scanner antivirus.Scanner) (*scanService, *recordingQueue, *models.File) {
+	t.Helper()
+	store, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, store.Put(context.Background(), "files/5", bytes.NewReader([]byte(content)), int64(len(content))))
+
+	storagePath := "files/5"
+	file := newTestFile(5, 7, nil, "setup.exe", false)
+	file.StoragePath = &storagePath
+	file.StorageType = storage.StorageTypeLocal
+	file.Size = int64(len(content))
+	file.Status = "active"
+	file.ScanStatus = models.ScanStatusPending
+
+	repo := &memoryFileRepository{files: map[uint]*models.File{5: file}}
+	queue := &recordingQueue{}
+	service := NewScanService(repo, store, scanner, queue, ScanOptions{MaxFileSize: 1024}, zap.NewNop()).(*scanService)
+	return service, queue, file
+}
+
+func TestScanService_ScheduleAndScan(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("clean", func(t *testing.T) {
+		service, queue, file := newScanFixture(t, "hello world", &fakeScanner{})
+
+		service.Schedule(ctx, file)
+		service.Schedule(ctx, file)
+		require.Len(t, queue.jobs, 1, "pending file should be queued once")
+		assert.Equal(t, jobs.TypeScan, queue.jobs[0].Type)
+		assert.Equal(t, "file:5", queue.jobs[0].Key)
+
+		require.NoError(t, queue.jobs[0].Run(ctx))
+		assert.Equal(t, models.ScanStatusClean, file.ScanStatus)
+	})
+
+	t.Run("infected", func(t *testing.T) {
+		service, _, file := newScanFixture(t, "X5O!P%@AP EICAR", &fakeScanner{})
+
+		require.NoError(t, service.Scan(ctx, 5))
+		assert.Equal(t, models.ScanStatusInfected, file.ScanStatus)
+	})
+
+	t.Run("scanner unavailable", func(t *testing.T) {
+		scanner := &fakeScanner{err: errors.New("连接clamd失败")}
+		service, _, file := newScanFixture(t, "hello", scanner)
+
+		require.NoError(t, service.Scan(ctx, 5))
+		assert.Equal(t, models.ScanStatusFailed, file.ScanStatus)
+
+		// 已有扫描结果的文件不再扫描
+		require.NoError(t, service.Scan(ctx, 5))
+		assert.Equal(t, 1, scanner.scanned)
+	})
+
+	t.Run("clamd size limit", func(t *testing.T) {
+		service, _, file := newScanFixture(t, "hello", &fakeScanner{err: antivirus.ErrSizeLimitExceeded})
+
+		require.NoError(t, service.Scan(ctx, 5))
+		assert.Equal(t, models.ScanStatusSkipped, file.ScanStatus)
+	})
+
+	t.Run("content missing", func(t *testing.T) {
+		service, _, file := newScanFixture(t, "hello", &fakeScanner{})
+		missing := "files/missing"
+		file.StoragePath = &missing
+
+		require.NoError(t, service.Scan(ctx, 5))
+		assert.Equal(t, models.ScanStatusFailed, file.ScanStatus)
+	})
+}
+
+func TestScanService_ScheduleSkips(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("scanner disabled", func(t *testing.T) {
+		service, queue, file := newScanFixture(t, "hello", nil)
+
+		service.Schedule(ctx, file)
+		assert.Empty(t, queue.jobs)
+		assert.Equal(t, models.ScanStatusSkipped, file.ScanStatus)
+	})
+
+	t.Run("over size limit", func(t *testing.T) {
+		service, queue, file := newScanFixture(t, "hello", &fakeScanner{})
+		file.Size = 2048
+
+		service.Schedule(ctx, file)
+		assert.Empty(t, queue.jobs)
+		assert.Equal(t, models.ScanStatusSkipped, file.ScanStatus)
+	})
+
+	t.Run("queue full", func(t *testing.T) {
+		service, queue, file := newScanFixture(t, "hello", &fakeScanner{})
+		queue.err = jobs.ErrQueueFull
+
+		service.Schedule(ctx, file)
+		assert.Equal(t, models.ScanStatusFailed, file.ScanStatus)
+	})
+}
@@ -0,0 +1,270 @@
+package file
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/cache"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	filerepo "cloudpan/internal/repository/file"
+	"cloudpan/internal/repository/models"
+)
+
+const (
+	maxSearchKeywordLength = 100 // 搜索关键词最大字符数
+	maxSearchFilterValues  = 20  // 每种筛选条件最多的取值个数
+)
+
+// SearchService 文件搜索服务接口
+//
+// 提供当前用户文件的全文搜索，包括：
+// 1. 关键词匹配文件名、标签和描述，可按标签、扩展名、MIME类型、大小范围和日期范围筛选
+// 2. 结果页缓存 search_result TTL，缓存只保存文件ID，读取时重新查询以跳过期间被删除的文件
+// 3. 记录每个用户最近的搜索关键词，可查询和清空
+//
+// 使用示例：
+//
+//	service := NewSearchService(searchRepo, cacheManager, historyStore, logger)
+//	result, err := service.Search(ctx, userID, SearchRequest{Keyword: "报告", Extensions: []string{"pdf"}, Page: 1, PageSize: 20})
+//	history, err := service.History(ctx, userID)
+type SearchService interface {
+	Search(ctx context.Context, userID uint, req SearchRequest) (*SearchResult, error)
+	History(ctx context.Context, userID uint) ([]cache.SearchHistoryEntry, error)
+	ClearHistory(ctx context.Context, userID uint) error
+}
+
+// SearchResultCache 搜索结果页缓存，由 cache.CacheManager 实现
+type SearchResultCache interface {
+	Get(key string, dest interface{}) error
+	SetWithTTL(key string, value interface{}, ttl time.Duration) error
+}
+
+// SearchRequest 文件搜索条件
+type SearchRequest struct {
+	Keyword    string     // 关键词
+	Tags       []string   // 标签，文件需包含全部标签
+	Extensions []string   // 扩展名，匹配任一，忽略大小写和开头的点
+	MimeTypes  []string   // MIME类型，以/结尾时按前缀匹配，匹配任一
+	MinSize    *int64     // 最小文件大小(字节)
+	MaxSize    *int64     // 最大文件大小(字节)
+	DateField  string     // 日期范围作用的字段(filerepo.SearchDateCreated等)，为空按修改时间
+	From       *time.Time // 开始时间(含)
+	To         *time.Time // 结束时间(不含)
+	Page       int        // 页码，从1开始
+	PageSize   int        // 每页数量
+}
+
+// SearchResult 文件搜索结果
+type SearchResult struct {
+	Files  []*models.File // 当前页的文件
+	Total  int64          // 匹配的文件总数
+	Cached bool           // 是否来自缓存
+}
+
+// cachedSearchPage 缓存的搜索结果页
+type cachedSearchPage struct {
+	IDs   []uint `json:"ids"`
+	Total int64  `json:"total"`
+}
+
+// searchService 文件搜索服务实现
+type searchService struct {
+	repo    filerepo.SearchRepository
+	cache   SearchResultCache
+	history cache.SearchHistoryStore
+	keys    *cache.KeyBuilder
+	ttl     time.Duration
+	logger  *zap.Logger
+	now     func() time.Time
+}
+
+// NewSearchService 创建文件搜索服务实例
+//
+// resultCache 和 history 可以为nil(如Redis未初始化)，此时不缓存结果页或不记录搜索历史
+func NewSearchService(repo filerepo.SearchRepository, resultCache SearchResultCache, history cache.SearchHistoryStore, logger *zap.Logger) SearchService {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &searchService{
+		repo:    repo,
+		cache:   resultCache,
+		history: history,
+		keys:    cache.NewKeyBuilder(),
+		ttl:     cache.NewTTLManager().GetTTL("search_result"),
+		logger:  logger,
+		now:     time.Now,
+	}
+}
+
+// Search 搜索当前用户的文件
+func (s *searchService) Search(ctx context.Context, userID uint, req SearchRequest) (*SearchResult, error) {
+	if userID == 0 {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "用户ID不能为空")
+	}
+	query, err := buildSearchQuery(userID, req)
+	if err != nil {
+		return nil, err
+	}
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	query.Offset = (req.Page - 1) * req.PageSize
+	query.Limit = req.PageSize
+
+	if query.Keyword != "" {
+		s.recordHistory(ctx, userID, query.Keyword)
+	}
+
+	cacheKey := s.keys.SearchResult(searchQueryHash(query))
+	if s.cache != nil {
+		var page cachedSearchPage
+		if err := s.cache.Get(cacheKey, &page); err == nil {
+			files, err := s.repo.FindActive(ctx, userID, page.IDs)
+			if err == nil {
+				return &SearchResult{Files: orderByIDs(files, page.IDs), Total: page.Total, Cached: true}, nil
+			}
+			s.logger.Warn("Failed to load cached search page", zap.Uint("user_id", userID), zap.Error(err))
+		}
+	}
+
+	files, total, err := s.repo.Search(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("搜索文件失败: %w", err)
+	}
+
+	if s.cache != nil {
+		page := cachedSearchPage{IDs: make([]uint, len(files)), Total: total}
+		for i, f := range files {
+			page.IDs[i] = f.ID
+		}
+		if err := s.cache.SetWithTTL(cacheKey, page, s.ttl); err != nil {
+			s.logger.Warn("Failed to cache search page", zap.Uint("user_id", userID), zap.Error(err))
+		}
+	}
+	return &SearchResult{Files: files, Total: total}, nil
+}
+
+// History 返回用户最近的搜索关键词，按搜索时间倒序
+func (s *searchService) History(ctx context.Context, userID uint) ([]cache.SearchHistoryEntry, error) {
+	if s.history == nil {
+		return []cache.SearchHistoryEntry{}, nil
+	}
+	entries, err := s.history.List(ctx, uint64(userID))
+	if err != nil {
+		return nil, fmt.Errorf("获取搜索历史失败: %w", err)
+	}
+	return entries, nil
+}
+
+// ClearHistory 清空用户的搜索历史
+func (s *searchService) ClearHistory(ctx context.Context, userID uint) error {
+	if s.history == nil {
+		return nil
+	}
+	if err := s.history.Clear(ctx, uint64(userID)); err != nil {
+		return fmt.Errorf("清空搜索历史失败: %w", err)
+	}
+	return nil
+}
+
+// recordHistory 记录搜索关键词，失败只记录日志
+func (s *searchService) recordHistory(ctx context.Context, userID uint, keyword string) {
+	if s.history == nil {
+		return
+	}
+	if err := s.history.Add(ctx, uint64(userID), keyword, s.now()); err != nil {
+		s.logger.Warn("Failed to record search history", zap.Uint("user_id", userID), zap.Error(err))
+	}
+}
+
+// buildSearchQuery 校验并规范化搜索条件，至少需要关键词或一个筛选条件
+func buildSearchQuery(userID uint, req SearchRequest) (filerepo.SearchQuery, error) {
+	query := filerepo.SearchQuery{
+		UserID:     userID,
+		Keyword:    strings.Join(strings.Fields(req.Keyword), " "),
+		Tags:       normalizeSearchValues(req.Tags, func(v string) string { return v }),
+		Extensions: normalizeSearchValues(req.Extensions, func(v string) string { return strings.ToLower(strings.TrimPrefix(v, ".")) }),
+		MimeTypes:  normalizeSearchValues(req.MimeTypes, strings.ToLower),
+		MinSize:    req.MinSize,
+		MaxSize:    req.MaxSize,
+		DateField:  req.DateField,
+		From:       req.From,
+		To:         req.To,
+	}
+
+	if utf8.RuneCountInString(query.Keyword) > maxSearchKeywordLength {
+		return query, pkgErrors.WrapError(pkgErrors.ErrInvalidInput, fmt.Sprintf("搜索关键词不能超过%d个字符", maxSearchKeywordLength))
+	}
+	if len(query.Tags) > maxSearchFilterValues || len(query.Extensions) > maxSearchFilterValues || len(query.MimeTypes) > maxSearchFilterValues {
+		return query, pkgErrors.WrapError(pkgErrors.ErrInvalidInput, fmt.Sprintf("每种筛选条件最多%d个值", maxSearchFilterValues))
+	}
+	if (query.MinSize != nil && *query.MinSize < 0) || (query.MaxSize != nil && *query.MaxSize < 0) {
+		return query, pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "文件大小不能为负数")
+	}
+	if query.MinSize != nil && query.MaxSize != nil && *query.MinSize > *query.MaxSize {
+		return query, pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "最小文件大小不能大于最大文件大小")
+	}
+	switch query.DateField {
+	case "":
+		query.DateField = filerepo.SearchDateUpdated
+	case filerepo.SearchDateCreated, filerepo.SearchDateUpdated:
+	default:
+		return query, pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "日期字段只能是created或updated")
+	}
+	if query.From != nil && query.To != nil && !query.From.Before(*query.To) {
+		return query, pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "开始时间必须早于结束时间")
+	}
+
+	if query.Keyword == "" && len(query.Tags) == 0 && len(query.Extensions) == 0 && len(query.MimeTypes) == 0 &&
+		query.MinSize == nil && query.MaxSize == nil && query.From == nil && query.To == nil {
+		return query, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "请输入搜索关键词或筛选条件")
+	}
+	return query, nil
+}
+
+// normalizeSearchValues 规范化筛选值：去掉空值和重复值并排序，使相同条件得到相同的缓存键
+func normalizeSearchValues(values []string, normalize func(string) string) []string {
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		value = normalize(strings.TrimSpace(value))
+		if value == "" || seen[value] {
+			continue
+		}
+		seen[value] = true
+		result = append(result, value)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// searchQueryHash 计算搜索条件(含用户和分页)的哈希，作为结果页缓存键
+func searchQueryHash(query filerepo.SearchQuery) string {
+	data, _ := json.Marshal(query)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// orderByIDs 按缓存的顺序排列文件，期间被删除的文件跳过
+func orderByIDs(files []*models.File, ids []uint) []*models.File {
+	byID := make(map[uint]*models.File, len(files))
+	for _, f := range files {
+		byID[f.ID] = f
+	}
+	ordered := make([]*models.File, 0, len(ids))
+	for _, id := range ids {
+		if f, ok := byID[id]; ok {
+			ordered = append(ordered, f)
+		}
+	}
+	return ordered
+}
@@ -0,0 +1,150 @@
+package file
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"cloudpan/internal/pkg/cache"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	filerepo "cloudpan/internal/repository/file"
+	"cloudpan/internal/repository/models"
+)
+
+// stubSearchRepository 返回固定结果并记录搜索条件的搜索仓储
+type stubSearchRepository struct {
+	files   map[uint]*models.File
+	queries []filerepo.SearchQuery
+}
+
+func (r *stubSearchRepository) Search(_ context.Context, query filerepo.SearchQuery) ([]*models.File, int64, error) {
+	r.queries = append(r.queries, query)
+	var files []*models.File
+	for id := uint(1); id <= uint(len(r.files)); id++ {
+		if f, ok := r.files[id]; ok {
+			files = append(files, f)
+		}
+	}
+	return files, int64(len(files)), nil
+}
+
+func (r *stubSearchRepository) FindActive(_ context.Context, _ uint, ids []uint) ([]*models.File, error) {
+	var files []*models.File
+	for _, id := range ids {
+		if f, ok := r.files[id]; ok {
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+// memorySearchCache 以JSON保存值的内存缓存
+type memorySearchCache struct {
+	values map[string]string
+}
+
+func (c *memorySearchCache) Get(key string, dest interface{}) error {
+	value, ok := c.values[key]
+	if !ok {
+		return errors.New("cache miss")
+	}
+	return json.Unmarshal([]byte(value), dest)
+}
+
+func (c *memorySearchCache) SetWithTTL(key string, value interface{}, _ time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	c.values[key] = string(data)
+	return nil
+}
+
+func newTestSearchFile(id uint, name string) *models.File {
+	f := &models.File{Name: name, UserID: 1, Status: "active"}
+	f.ID = id
+	return f
+}
+
+func TestSearchService_Search(t *testing.T) {
+	ctx := context.Background()
+	repo := &stubSearchRepository{files: map[uint]*models.File{
+		1: newTestSearchFile(1, "季度报告.pdf"),
+		2: newTestSearchFile(2, "年度报告.pdf"),
+	}}
+	resultCache := &memorySearchCache{values: map[string]string{}}
+	history := cache.NewMemorySearchHistoryStore(time.Hour)
+	svc := NewSearchService(repo, resultCache, history, nil)
+
+	req := SearchRequest{Keyword: "  报告 ", Extensions: []string{".PDF", "pdf"}, MimeTypes: []string{"Application/PDF"}, Page: 1, PageSize: 20}
+	result, err := svc.Search(ctx, 1, req)
+	require.NoError(t, err)
+	assert.False(t, result.Cached)
+	assert.Equal(t, int64(2), result.Total)
+	require.Len(t, repo.queries, 1)
+	query := repo.queries[0]
+	assert.Equal(t, "报告", query.Keyword)
+	assert.Equal(t, []string{"pdf"}, query.Extensions)
+	assert.Equal(t, []string{"application/pdf"}, query.MimeTypes)
+	assert.Equal(t, filerepo.SearchDateUpdated, query.DateField)
+	assert.Equal(t, 20, query.Limit)
+
+	// 相同条件命中缓存，期间被删除的文件跳过
+	delete(repo.files, 1)
+	result, err = svc.Search(ctx, 1, req)
+	require.NoError(t, err)
+	assert.True(t, result.Cached)
+	assert.Len(t, repo.queries, 1)
+	require.Len(t, result.Files, 1)
+	assert.Equal(t, uint(2), result.Files[0].ID)
+
+	// 不同页不共享缓存
+	req.Page = 2
+	_, err = svc.Search(ctx, 1, req)
+	require.NoError(t, err)
+	assert.Len(t, repo.queries, 2)
+	assert.Equal(t, 20, repo.queries[1].Offset)
+
+	entries, err := svc.History(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "报告", entries[0].Query)
+	require.NoError(t, svc.ClearHistory(ctx, 1))
+	entries, err = svc.History(ctx, 1)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestSearchService_Validation(t *testing.T) {
+	ctx := context.Background()
+	svc := NewSearchService(&stubSearchRepository{}, nil, nil, nil)
+	size := func(v int64) *int64 { return &v }
+	from := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(-time.Hour)
+
+	invalid := []SearchRequest{
+		{},
+		{Keyword: "   "},
+		{MinSize: size(-1)},
+		{MinSize: size(10), MaxSize: size(5)},
+		{Keyword: "a", DateField: "deleted"},
+		{From: &from, To: &to},
+	}
+	for _, req := range invalid {
+		_, err := svc.Search(ctx, 1, req)
+		assert.True(t, pkgErrors.IsValidationError(err), "%+v: %v", req, err)
+	}
+
+	// 只有筛选条件也可以搜索，未配置缓存和历史时正常返回
+	result, err := svc.Search(ctx, 1, SearchRequest{MinSize: size(1024), PageSize: 10})
+	require.NoError(t, err)
+	assert.False(t, result.Cached)
+	entries, err := svc.History(ctx, 1)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
@@ -0,0 +1,99 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/storage"
+)
+
+// RecoveryChunkStore 崩溃恢复时核对分片记录，由 filerepo.UploadChunkRepository 实现
+type RecoveryChunkStore interface {
+	ExistsByStoragePath(ctx context.Context, storagePath string) (bool, error)
+	DeleteByStoragePath(ctx context.Context, storagePath string) (int64, error)
+}
+
+// RecoveryObjectStore 崩溃恢复时检查和删除存储对象，由 storage.Storage 实现
+type RecoveryObjectStore interface {
+	Exists(ctx context.Context, path string) (bool, error)
+	Delete(ctx context.Context, path string) error
+}
+
+// StorageRecoveryResult 本地存储崩溃恢复和数据库核对结果
+type StorageRecoveryResult struct {
+	Completed      int // 恢复时完成写入的对象数
+	Discarded      int // 删除临时数据的未完成写入数
+	OrphanTemps    int // 没有日志记录、已删除的临时文件数
+	OrphanChunks   int // 完成写入但没有分片记录(上传方未收到成功响应)、已删除的分片对象数
+	ChunkRowsReset int // 分片对象不存在、已删除的分片记录数，上传方需重新上传这些分片
+}
+
+// Repairs 返回修复的总数
+func (r *StorageRecoveryResult) Repairs() int {
+	return r.Completed + r.Discarded + r.OrphanTemps + r.OrphanChunks + r.ChunkRowsReset
+}
+
+// ReconcileStorageRecovery 根据本地存储的崩溃恢复结果核对数据库记录
+//
+// 只核对分片对象：恢复时完成的分片写入在崩溃前未返回成功，没有分片记录引用时删除对象，
+// 上传方会重新上传；未完成的分片写入对象不存在时删除引用它的分片记录。
+// 合并后的文件在激活前处于上传中状态，预览等派生对象会重新生成，都不需要修复
+func ReconcileStorageRecovery(ctx context.Context, report *storage.RecoveryReport, objects RecoveryObjectStore, chunks RecoveryChunkStore, logger *zap.Logger) (*StorageRecoveryResult, error) {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	result := &StorageRecoveryResult{
+		Completed:   len(report.Completed),
+		Discarded:   len(report.Discarded),
+		OrphanTemps: report.Orphans,
+	}
+
+	for _, objectPath := range report.Completed {
+		if !isChunkPath(objectPath) {
+			continue
+		}
+		referenced, err := chunks.ExistsByStoragePath(ctx, objectPath)
+		if err != nil {
+			return result, fmt.Errorf("核对分片记录失败: %w", err)
+		}
+		if referenced {
+			continue
+		}
+		if err := objects.Delete(ctx, objectPath); err != nil {
+			return result, fmt.Errorf("删除未登记的分片失败: %w", err)
+		}
+		result.OrphanChunks++
+		logger.Warn("Removed unregistered chunk recovered from journal", zap.String("path", objectPath))
+	}
+
+	for _, objectPath := range report.Discarded {
+		if !isChunkPath(objectPath) {
+			continue
+		}
+		exists, err := objects.Exists(ctx, objectPath)
+		if err != nil {
+			return result, fmt.Errorf("检查分片对象失败: %w", err)
+		}
+		if exists {
+			continue
+		}
+		removed, err := chunks.DeleteByStoragePath(ctx, objectPath)
+		if err != nil {
+			return result, fmt.Errorf("删除分片记录失败: %w", err)
+		}
+		if removed > 0 {
+			result.ChunkRowsReset += int(removed)
+			logger.Warn("Reset chunk records of missing chunk object", zap.String("path", objectPath), zap.Int64("records", removed))
+		}
+	}
+
+	return result, nil
+}
+
+// isChunkPath 检查存储路径是否为上传分片
+func isChunkPath(objectPath string) bool {
+	return strings.HasPrefix(objectPath, chunkPathPrefix+"/")
+}
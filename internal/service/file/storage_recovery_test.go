@@ -0,0 +1,49 @@
+package file
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"cloudpan/internal/pkg/storage"
+	"cloudpan/internal/repository/models"
+)
+
+func TestReconcileStorageRecovery(t *testing.T) {
+	ctx := context.Background()
+	store, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	chunks := newMemoryChunkRepository()
+
+	registered := chunkStoragePath("u1", 0)
+	unregistered := chunkStoragePath("u1", 1)
+	missing := chunkStoragePath("u2", 0)
+	for _, objectPath := range []string{registered, unregistered, "files/1/merged"} {
+		require.NoError(t, store.Put(ctx, objectPath, strings.NewReader("data"), 4))
+	}
+	require.NoError(t, chunks.SaveChunk(ctx, &models.FileUploadChunk{UploadID: "u1", ChunkIndex: 0, StoragePath: registered}))
+	require.NoError(t, chunks.SaveChunk(ctx, &models.FileUploadChunk{UploadID: "u2", ChunkIndex: 0, StoragePath: missing}))
+
+	report := &storage.RecoveryReport{
+		Completed: []string{registered, unregistered, "files/1/merged"},
+		Discarded: []string{missing, "previews/1/thumb"},
+		Orphans:   1,
+	}
+	result, err := ReconcileStorageRecovery(ctx, report, store, chunks, nil)
+	require.NoError(t, err)
+	assert.Equal(t, &StorageRecoveryResult{Completed: 3, Discarded: 2, OrphanTemps: 1, OrphanChunks: 1, ChunkRowsReset: 1}, result)
+	assert.Equal(t, 8, result.Repairs())
+
+	// 已登记的分片和非分片对象保留，未登记的分片删除
+	for path, want := range map[string]bool{registered: true, unregistered: false, "files/1/merged": true} {
+		exists, err := store.Exists(ctx, path)
+		require.NoError(t, err)
+		assert.Equal(t, want, exists, path)
+	}
+	remaining, err := chunks.ListByUploadID(ctx, "u2")
+	require.NoError(t, err)
+	assert.Empty(t, remaining) // 对象不存在的分片需重新上传
+}
@@ -0,0 +1,304 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	filerepo "cloudpan/internal/repository/file"
+	"cloudpan/internal/repository/models"
+)
+
+// 文件标签限制
+const (
+	MaxTagLength      = 40   // 单个标签的最大字符数
+	MaxTagsPerFile    = 20   // 单个文件的最大标签数
+	maxTagColorLength = 20   // 标签颜色的最大字符数
+	maxFileTagsLength = 1000 // 文件tags列(逗号分隔)的最大字符数
+)
+
+// TagService 文件标签服务接口
+//
+// 标签以 file_tags 表为准，同一文件上的标签不区分大小写唯一，唯一性在服务层检查：
+// 1. 文件标签：查看、添加和删除文件上的标签，文件必须属于用户且可用
+// 2. 用户标签：列出用户的全部标签及每个标签下的可用文件数
+//
+// 添加和删除时同步更新文件的tags列(逗号分隔，供关键字搜索使用)：添加时追加，删除时只移除该标签，
+// 文件夹规则等其他来源写入tags列的标签不受影响
+//
+// 使用示例：
+//
+//	service := NewTagService(tagRepo, fileRepo, logger)
+//	tag, err := service.AddTag(ctx, userID, fileID, &AddTagRequest{Tag: "合同"})
+//	err = service.RemoveTag(ctx, userID, fileID, "合同")
+//	counts, err := service.ListUserTags(ctx, userID)
+type TagService interface {
+	ListFileTags(ctx context.Context, userID, fileID uint) ([]*FileTag, error)
+	AddTag(ctx context.Context, userID, fileID uint, req *AddTagRequest) (*FileTag, error)
+	RemoveTag(ctx context.Context, userID, fileID uint, tag string) error
+
+	ListUserTags(ctx context.Context, userID uint) ([]filerepo.TagCount, error)
+}
+
+// AddTagRequest 添加文件标签请求
+type AddTagRequest struct {
+	Tag   string // 标签名称，首尾空白会被去除
+	Color string // 标签颜色，如#1890ff，可以为空
+}
+
+// FileTag 文件上的标签
+type FileTag struct {
+	Tag       string    `json:"tag"`             // 标签名称
+	Color     *string   `json:"color,omitempty"` // 标签颜色
+	CreatedAt time.Time `json:"created_at"`      // 添加时间
+}
+
+// tagService 文件标签服务实现
+type tagService struct {
+	tagRepo  filerepo.TagRepository
+	fileRepo filerepo.FileRepository
+	logger   *zap.Logger
+}
+
+// NewTagService 创建文件标签服务实例
+func NewTagService(tagRepo filerepo.TagRepository, fileRepo filerepo.FileRepository, logger *zap.Logger) TagService {
+	return &tagService{
+		tagRepo:  tagRepo,
+		fileRepo: fileRepo,
+		logger:   logger,
+	}
+}
+
+// ListFileTags 列出文件上的标签，按添加顺序排列
+func (s *tagService) ListFileTags(ctx context.Context, userID, fileID uint) ([]*FileTag, error) {
+	if _, err := s.getOwnedActive(ctx, userID, fileID); err != nil {
+		return nil, err
+	}
+	tags, err := s.tagRepo.ListByFile(ctx, fileID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取文件标签失败: %w", err)
+	}
+	views := make([]*FileTag, len(tags))
+	for i, tag := range tags {
+		views[i] = newFileTagView(tag)
+	}
+	return views, nil
+}
+
+// AddTag 为文件添加标签，文件上已有同名标签(不区分大小写)时返回冲突错误
+func (s *tagService) AddTag(ctx context.Context, userID, fileID uint, req *AddTagRequest) (*FileTag, error) {
+	if req == nil {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "请求不能为空")
+	}
+	name, err := NormalizeTag(req.Tag)
+	if err != nil {
+		return nil, err
+	}
+	color := strings.TrimSpace(req.Color)
+	if utf8.RuneCountInString(color) > maxTagColorLength {
+		return nil, pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "标签颜色不能超过%d个字符", maxTagColorLength)
+	}
+
+	file, err := s.getOwnedActive(ctx, userID, fileID)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := s.tagRepo.ListByFile(ctx, fileID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取文件标签失败: %w", err)
+	}
+	if findTag(existing, name) != nil {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrResourceExists, "文件已有该标签")
+	}
+	if len(existing) >= MaxTagsPerFile {
+		return nil, pkgErrors.WrapErrorf(pkgErrors.ErrOperationNotAllowed, "单个文件最多添加%d个标签", MaxTagsPerFile)
+	}
+
+	tag := &models.FileTag{FileID: fileID, UserID: userID, Tag: name}
+	if color != "" {
+		tag.Color = &color
+	}
+	if err := s.tagRepo.Create(ctx, tag); err != nil {
+		return nil, fmt.Errorf("添加文件标签失败: %w", err)
+	}
+	s.syncColumn(ctx, file, func(tags []string) []string {
+		return append(tags, name)
+	})
+
+	s.logger.Info("File tag added",
+		zap.Uint("user_id", userID),
+		zap.Uint("file_id", fileID),
+		zap.String("tag", name))
+	return newFileTagView(tag), nil
+}
+
+// RemoveTag 删除文件上的标签(不区分大小写)，标签不存在时返回未找到错误
+func (s *tagService) RemoveTag(ctx context.Context, userID, fileID uint, tag string) error {
+	name, err := NormalizeTag(tag)
+	if err != nil {
+		return err
+	}
+	file, err := s.getOwnedActive(ctx, userID, fileID)
+	if err != nil {
+		return err
+	}
+	existing, err := s.tagRepo.ListByFile(ctx, fileID, userID)
+	if err != nil {
+		return fmt.Errorf("获取文件标签失败: %w", err)
+	}
+	found := findTag(existing, name)
+	if found == nil {
+		return pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "文件没有该标签")
+	}
+
+	if err := s.tagRepo.Delete(ctx, found.ID); err != nil {
+		return fmt.Errorf("删除文件标签失败: %w", err)
+	}
+	s.syncColumn(ctx, file, func(tags []string) []string {
+		kept := tags[:0]
+		for _, t := range tags {
+			if !strings.EqualFold(t, found.Tag) {
+				kept = append(kept, t)
+			}
+		}
+		return kept
+	})
+
+	s.logger.Info("File tag removed",
+		zap.Uint("user_id", userID),
+		zap.Uint("file_id", fileID),
+		zap.String("tag", found.Tag))
+	return nil
+}
+
+// ListUserTags 列出用户的全部标签及每个标签下的可用文件数
+func (s *tagService) ListUserTags(ctx context.Context, userID uint) ([]filerepo.TagCount, error) {
+	if userID == 0 {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "用户ID不能为空")
+	}
+	counts, err := s.tagRepo.CountByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取用户标签失败: %w", err)
+	}
+	if counts == nil {
+		counts = []filerepo.TagCount{}
+	}
+	return counts, nil
+}
+
+// syncColumn 按 update 修改文件的tags列
+//
+// tags列只是搜索用的冗余数据，更新失败或超长时只记录日志，不影响标签本身
+func (s *tagService) syncColumn(ctx context.Context, file *models.File, update func([]string) []string) {
+	var current []string
+	if file.Tags != nil {
+		current = splitTags(*file.Tags)
+	}
+	joined := joinTags(update(current))
+	if len(joined) > maxFileTagsLength {
+		s.logger.Warn("File tags column too long, skipped sync",
+			zap.Uint("file_id", file.ID),
+			zap.Int("length", len(joined)))
+		return
+	}
+
+	var tags *string
+	if joined != "" {
+		tags = &joined
+	}
+	if err := s.fileRepo.UpdateTags(ctx, file.ID, tags); err != nil {
+		s.logger.Warn("Failed to sync file tags column", zap.Uint("file_id", file.ID), zap.Error(err))
+		return
+	}
+	file.Tags = tags
+}
+
+// getOwnedActive 获取属于用户的可用文件
+func (s *tagService) getOwnedActive(ctx context.Context, userID, fileID uint) (*models.File, error) {
+	return getOwnedActiveFile(ctx, s.fileRepo, userID, fileID)
+}
+
+// getOwnedActiveFile 获取属于用户的可用文件
+func getOwnedActiveFile(ctx context.Context, fileRepo filerepo.FileRepository, userID, fileID uint) (*models.File, error) {
+	if userID == 0 || fileID == 0 {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "用户ID和文件ID不能为空")
+	}
+	file, err := fileRepo.GetByID(ctx, fileID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "文件不存在")
+		}
+		return nil, fmt.Errorf("获取文件失败: %w", err)
+	}
+	if file.UserID != userID {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, "无权访问该文件")
+	}
+	if !file.IsActive() {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "文件当前不可操作")
+	}
+	return file, nil
+}
+
+// NormalizeTag 去除标签首尾空白并校验：不能为空、不能超过 MaxTagLength 个字符、不能包含逗号和控制字符
+func NormalizeTag(tag string) (string, error) {
+	tag = strings.TrimSpace(tag)
+	if tag == "" {
+		return "", pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "标签不能为空")
+	}
+	if utf8.RuneCountInString(tag) > MaxTagLength {
+		return "", pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "标签不能超过%d个字符", MaxTagLength)
+	}
+	if strings.ContainsFunc(tag, func(r rune) bool { return r == ',' || unicode.IsControl(r) }) {
+		return "", pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "标签不能包含逗号或控制字符")
+	}
+	return tag, nil
+}
+
+// findTag 按名称查找标签，不区分大小写
+func findTag(tags []*models.FileTag, name string) *models.FileTag {
+	for _, tag := range tags {
+		if strings.EqualFold(tag.Tag, name) {
+			return tag
+		}
+	}
+	return nil
+}
+
+// newFileTagView 转换为接口返回的标签
+func newFileTagView(tag *models.FileTag) *FileTag {
+	return &FileTag{Tag: tag.Tag, Color: tag.Color, CreatedAt: tag.CreatedAt}
+}
+
+// splitTags 拆分逗号分隔的标签，忽略空白项
+func splitTags(value string) []string {
+	var tags []string
+	for _, tag := range strings.Split(value, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// joinTags 去重(不区分大小写)后以逗号连接标签
+func joinTags(tags []string) string {
+	seen := make(map[string]bool, len(tags))
+	var result []string
+	for _, tag := range tags {
+		key := strings.ToLower(tag)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, tag)
+	}
+	return strings.Join(result, ",")
+}
@@ -0,0 +1,150 @@
+package file
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	filerepo "cloudpan/internal/repository/file"
+	"cloudpan/internal/repository/models"
+)
+
+// memoryTags 内存文件标签仓储
+type memoryTags struct {
+	tags   []*models.FileTag
+	nextID uint
+}
+
+func (m *memoryTags) ListByFile(_ context.Context, fileID, userID uint) ([]*models.FileTag, error) {
+	var tags []*models.FileTag
+	for _, tag := range m.tags {
+		if tag.FileID == fileID && tag.UserID == userID {
+			tags = append(tags, tag)
+		}
+	}
+	return tags, nil
+}
+
+func (m *memoryTags) Create(_ context.Context, tag *models.FileTag) error {
+	m.nextID++
+	tag.ID = m.nextID
+	m.tags = append(m.tags, tag)
+	return nil
+}
+
+func (m *memoryTags) Delete(_ context.Context, id uint) error {
+	for i, tag := range m.tags {
+		if tag.ID == id {
+			m.tags = append(m.tags[:i], m.tags[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (m *memoryTags) CountByUser(_ context.Context, userID uint) ([]filerepo.TagCount, error) {
+	var counts []filerepo.TagCount
+	for _, tag := range m.tags {
+		if tag.UserID == userID {
+			counts = append(counts, filerepo.TagCount{Tag: tag.Tag, Count: 1})
+		}
+	}
+	return counts, nil
+}
+
+func newTagFixture() (TagService, *memoryTags, *memoryFileRepository) {
+	automated := "Invoice"
+	files := &memoryFileRepository{files: map[uint]*models.File{
+		1: {Name: "a.pdf", UserID: 7, Status: "active", Tags: &automated},
+		2: {Name: "b.pdf", UserID: 8, Status: "active"},
+		3: {Name: "c.pdf", UserID: 7, Status: "deleted"},
+	}}
+	for id, f := range files.files {
+		f.ID = id
+	}
+	tags := &memoryTags{}
+	return NewTagService(tags, files, zap.NewNop()), tags, files
+}
+
+func TestTagService_AddAndRemove(t *testing.T) {
+	service, tags, files := newTagFixture()
+	ctx := context.Background()
+
+	tag, err := service.AddTag(ctx, 7, 1, &AddTagRequest{Tag: "  合同 ", Color: "#1890ff"})
+	require.NoError(t, err)
+	assert.Equal(t, "合同", tag.Tag)
+	require.NotNil(t, tag.Color)
+	assert.Equal(t, "Invoice,合同", *files.files[1].Tags)
+
+	// 同一文件上的标签不区分大小写唯一
+	_, err = service.AddTag(ctx, 7, 1, &AddTagRequest{Tag: "Draft"})
+	require.NoError(t, err)
+	_, err = service.AddTag(ctx, 7, 1, &AddTagRequest{Tag: "draft"})
+	assert.ErrorIs(t, err, pkgErrors.ErrResourceExists)
+	assert.Len(t, tags.tags, 2)
+
+	list, err := service.ListFileTags(ctx, 7, 1)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, "合同", list[0].Tag)
+
+	// 删除只移除该标签，文件夹规则写入的标签保留
+	require.NoError(t, service.RemoveTag(ctx, 7, 1, "DRAFT"))
+	assert.Equal(t, "Invoice,合同", *files.files[1].Tags)
+	require.NoError(t, service.RemoveTag(ctx, 7, 1, "合同"))
+	assert.Equal(t, "Invoice", *files.files[1].Tags)
+	assert.Empty(t, tags.tags)
+
+	err = service.RemoveTag(ctx, 7, 1, "合同")
+	assert.ErrorIs(t, err, pkgErrors.ErrResourceNotFound)
+}
+
+func TestTagService_Validation(t *testing.T) {
+	service, _, _ := newTagFixture()
+	ctx := context.Background()
+
+	for _, name := range []string{"", "  ", "a,b", "tab\there", strings.Repeat("标", MaxTagLength+1)} {
+		_, err := service.AddTag(ctx, 7, 1, &AddTagRequest{Tag: name})
+		assert.Error(t, err, name)
+	}
+
+	_, err := service.AddTag(ctx, 7, 2, &AddTagRequest{Tag: "合同"})
+	assert.ErrorIs(t, err, pkgErrors.ErrPermissionDenied)
+	_, err = service.AddTag(ctx, 7, 3, &AddTagRequest{Tag: "合同"})
+	assert.ErrorIs(t, err, pkgErrors.ErrOperationNotAllowed)
+	_, err = service.AddTag(ctx, 7, 9, &AddTagRequest{Tag: "合同"})
+	assert.ErrorIs(t, err, pkgErrors.ErrResourceNotFound)
+}
+
+func TestTagService_MaxTagsPerFile(t *testing.T) {
+	service, _, _ := newTagFixture()
+	ctx := context.Background()
+
+	for i := 0; i < MaxTagsPerFile; i++ {
+		_, err := service.AddTag(ctx, 7, 1, &AddTagRequest{Tag: strings.Repeat("x", i+1)})
+		require.NoError(t, err)
+	}
+	_, err := service.AddTag(ctx, 7, 1, &AddTagRequest{Tag: "one-more"})
+	assert.ErrorIs(t, err, pkgErrors.ErrOperationNotAllowed)
+}
+
+func TestTagService_ListUserTags(t *testing.T) {
+	service, _, _ := newTagFixture()
+	ctx := context.Background()
+
+	counts, err := service.ListUserTags(ctx, 7)
+	require.NoError(t, err)
+	assert.NotNil(t, counts)
+	assert.Empty(t, counts)
+
+	_, err = service.AddTag(ctx, 7, 1, &AddTagRequest{Tag: "合同"})
+	require.NoError(t, err)
+	counts, err = service.ListUserTags(ctx, 7)
+	require.NoError(t, err)
+	assert.Equal(t, []filerepo.TagCount{{Tag: "合同", Count: 1}}, counts)
+}
@@ -0,0 +1,807 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/cache"
+	"cloudpan/internal/pkg/config"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/storage"
+	"cloudpan/internal/pkg/utils"
+	filerepo "cloudpan/internal/repository/file"
+	"cloudpan/internal/repository/models"
+)
+
+// 文件所有权转移默认值
+const (
+	DefaultTransferExpiry   = 7 * 24 * time.Hour
+	DefaultMaxTransferItems = 10000
+
+	// maxTransferMessageLength 发起方留言和强制转移原因的最大字符数
+	maxTransferMessageLength = 500
+)
+
+// TransferService 文件所有权转移服务接口
+//
+// 1. 发起：所有者把文件或文件夹(连同全部子项)转给另一个用户，选择已有分享保留(改由接收方分享)或停用
+// 2. 接受、拒绝和取消：接收方在有效期内接受或拒绝，发起方在完成前可以取消；过期未接受的转移由维护任务标记为过期
+// 3. 完成：接受后立即把文件移到接收方的根目录(同名时自动重命名)，全部子项改到接收方名下，
+// 存储用量(含历史版本)从发起方转到接收方；完成失败时转移保持已接受状态，双方都可以重试
+// 4. 强制转移：管理员为离职交接直接完成转移，不需要接收方接受，不检查接收方的存储配额；
+// 未指定文件时把原所有者根目录下的全部文件转到接收方根目录下以原所有者命名的文件夹中
+//
+// 转移进行中的文件及其上级文件夹和子项不能再次发起转移。文件标签属于原所有者，不随文件转移
+//
+// 使用示例：
+//
+//	service := NewTransferService(fileRepo, folderRepo, transferRepo, userRepo, versionRepo, fileService, cacheManager, folderLimiter, TransferOptionsFromConfig(cfg.Storage.Transfer), logger)
+//	transfer, err := service.Initiate(ctx, userID, &TransferRequest{FileID: folderID, ToUserID: 8})
+//	transfer, err = service.Accept(ctx, 8, transfer.ID)
+//	result, err := service.ForceTransfer(ctx, adminID, &ForceTransferRequest{FromUserID: 7, ToUserID: 8, Reason: "离职交接"})
+type TransferService interface {
+	Initiate(ctx context.Context, userID uint, req *TransferRequest) (*models.FileTransfer, error)
+	Accept(ctx context.Context, userID, transferID uint) (*models.FileTransfer, error)
+	Complete(ctx context.Context, userID, transferID uint) (*models.FileTransfer, error)
+	Decline(ctx context.Context, userID, transferID uint) (*models.FileTransfer, error)
+	Cancel(ctx context.Context, userID, transferID uint) (*models.FileTransfer, error)
+
+	Get(ctx context.Context, userID, transferID uint) (*models.FileTransfer, error)
+	List(ctx context.Context, userID uint, direction, status string, page, pageSize int) ([]*models.FileTransfer, int64, error)
+
+	ForceTransfer(ctx context.Context, adminID uint, req *ForceTransferRequest) (*ForceTransferResult, error)
+	ExpirePending(ctx context.Context, limit int) (int64, error)
+}
+
+// TransferVersionLister 历史版本查询，由 filerepo.VersionRepository 实现
+type TransferVersionLister interface {
+	ListByFiles(ctx context.Context, fileIDs []uint) ([]*models.FileVersion, error)
+}
+
+// TransferOptions 文件所有权转移选项
+type TransferOptions struct {
+	Expiry   time.Duration // 转移等待接受的有效期
+	MaxItems int           // 单次转移的最大文件数(含文件夹)
+}
+
+// TransferOptionsFromConfig 从所有权转移配置生成选项
+func TransferOptionsFromConfig(cfg config.TransferConfig) TransferOptions {
+	return TransferOptions{
+		Expiry:   cfg.Expiry,
+		MaxItems: cfg.MaxItems,
+	}
+}
+
+// TransferRequest 发起所有权转移请求
+type TransferRequest struct {
+	FileID    uint   `json:"file_id" binding:"required"`                       // 转移的文件或文件夹ID
+	ToUserID  uint   `json:"to_user_id" binding:"required"`                    // 接收方用户ID
+	ShareMode string `json:"share_mode" binding:"omitempty,oneof=keep revoke"` // 已有分享的处理方式，默认keep
+	Message   string `json:"message"`                                          // 给接收方的留言，最多500个字符
+}
+
+// ForceTransferRequest 管理员强制转移请求
+type ForceTransferRequest struct {
+	FromUserID uint   `json:"from_user_id" binding:"required"`                  // 原所有者ID
+	ToUserID   uint   `json:"to_user_id" binding:"required"`                    // 接收方用户ID
+	FileID     uint   `json:"file_id"`                                          // 转移的文件或文件夹ID，为0时转移原所有者的全部文件
+	ShareMode  string `json:"share_mode" binding:"omitempty,oneof=keep revoke"` // 已有分享的处理方式，默认keep
+	Reason     string `json:"reason" binding:"required"`                        // 强制转移的原因，最多500个字符
+}
+
+// ForceTransferResult 强制转移结果
+type ForceTransferResult struct {
+	Folder    *models.File           `json:"folder,omitempty"` // 转移全部文件时在接收方根目录创建的文件夹
+	Transfers []*models.FileTransfer `json:"transfers"`        // 每个转移的文件或文件夹一条记录
+	Completed int                    `json:"completed"`        // 已完成的转移数
+	Failed    int                    `json:"failed"`           // 完成失败的转移数，失败原因见记录的error，可以重试
+}
+
+// transferService 文件所有权转移服务实现
+type transferService struct {
+	fileRepo   filerepo.FileRepository
+	folderRepo filerepo.FolderRepository
+	transfers  filerepo.TransferRepository
+	accounts   StorageAccountStore
+	versions   TransferVersionLister
+	checksums  ChecksumInvalidator
+	cache      CacheDeleter
+	folders    FolderLimiter
+	keys       *cache.KeyBuilder
+	options    TransferOptions
+	logger     *zap.Logger
+	now        func() time.Time
+}
+
+// NewTransferService 创建文件所有权转移服务实例
+//
+// versions 为nil时只转移文件当前内容的存储用量；checksums 和 cacheDeleter 可以为nil(如Redis未初始化)，
+// 此时跳过对应的失效处理；folders 为nil时不限制接收方的文件夹层级和子项数
+func NewTransferService(fileRepo filerepo.FileRepository, folderRepo filerepo.FolderRepository, transferRepo filerepo.TransferRepository,
+	accounts StorageAccountStore, versions TransferVersionLister, checksums ChecksumInvalidator, cacheDeleter CacheDeleter,
+	folders FolderLimiter, options TransferOptions, logger *zap.Logger) TransferService {
+	if options.Expiry <= 0 {
+		options.Expiry = DefaultTransferExpiry
+	}
+	if options.MaxItems <= 0 {
+		options.MaxItems = DefaultMaxTransferItems
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &transferService{
+		fileRepo:   fileRepo,
+		folderRepo: folderRepo,
+		transfers:  transferRepo,
+		accounts:   accounts,
+		versions:   versions,
+		checksums:  checksums,
+		cache:      cacheDeleter,
+		folders:    folders,
+		keys:       cache.NewKeyBuilder(),
+		options:    options,
+		logger:     logger,
+		now:        time.Now,
+	}
+}
+
+// Initiate 发起所有权转移，接收方需在有效期内接受
+func (s *transferService) Initiate(ctx context.Context, userID uint, req *TransferRequest) (*models.FileTransfer, error) {
+	if userID == 0 || req == nil || req.FileID == 0 || req.ToUserID == 0 {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "用户ID、文件ID和接收方不能为空")
+	}
+	if req.ToUserID == userID {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "不能把文件转移给自己")
+	}
+	shareMode, err := normalizeShareMode(req.ShareMode)
+	if err != nil {
+		return nil, err
+	}
+	message, err := normalizeTransferMessage(req.Message, false)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.recipient(ctx, req.ToUserID); err != nil {
+		return nil, err
+	}
+
+	root, err := s.getFile(ctx, req.FileID)
+	if err != nil {
+		return nil, err
+	}
+	if root.UserID != userID {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, "只能转移自己的文件")
+	}
+	if err := s.checkTransferable(ctx, root); err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	expiresAt := now.Add(s.options.Expiry)
+	transfer := &models.FileTransfer{
+		FileID:     root.ID,
+		FileName:   root.Name,
+		IsFolder:   root.IsFolder,
+		FromUserID: userID,
+		ToUserID:   req.ToUserID,
+		Status:     models.TransferStatusPending,
+		ShareMode:  shareMode,
+		Message:    message,
+		ExpiresAt:  &expiresAt,
+	}
+	if err := s.transfers.Create(ctx, transfer); err != nil {
+		return nil, fmt.Errorf("创建转移失败: %w", err)
+	}
+
+	s.logger.Info("File transfer initiated",
+		zap.Uint("transfer_id", transfer.ID),
+		zap.Uint("file_id", root.ID),
+		zap.Uint("from_user_id", userID),
+		zap.Uint("to_user_id", req.ToUserID))
+	return transfer, nil
+}
+
+// Accept 接收方接受转移并立即完成
+//
+// 完成失败时转移保持已接受状态并记录失败原因，可以通过 Complete 重试
+func (s *transferService) Accept(ctx context.Context, userID, transferID uint) (*models.FileTransfer, error) {
+	transfer, err := s.getVisible(ctx, userID, transferID)
+	if err != nil {
+		return nil, err
+	}
+	if transfer.ToUserID != userID {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, "只有接收方可以接受转移")
+	}
+	if transfer.Status != models.TransferStatusPending {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "转移不是等待接受状态")
+	}
+	now := s.now()
+	if transfer.ExpiresAt != nil && !now.Before(*transfer.ExpiresAt) {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "转移已过期")
+	}
+
+	ok, err := s.transfers.Respond(ctx, transfer.ID, []string{models.TransferStatusPending}, models.TransferStatusAccepted, now)
+	if err != nil {
+		return nil, fmt.Errorf("接受转移失败: %w", err)
+	}
+	if !ok {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "转移状态已变更，请刷新后重试")
+	}
+	transfer.Status, transfer.RespondedAt = models.TransferStatusAccepted, &now
+
+	if err := s.complete(ctx, transfer, nil, "/", true); err != nil {
+		return nil, err
+	}
+	return transfer, nil
+}
+
+// Complete 重试完成已接受的转移，发起方和接收方都可以重试
+func (s *transferService) Complete(ctx context.Context, userID, transferID uint) (*models.FileTransfer, error) {
+	transfer, err := s.getVisible(ctx, userID, transferID)
+	if err != nil {
+		return nil, err
+	}
+	if transfer.Status != models.TransferStatusAccepted {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "只能重试已接受但未完成的转移")
+	}
+	if err := s.complete(ctx, transfer, nil, "/", transfer.ForcedBy == nil); err != nil {
+		return nil, err
+	}
+	return transfer, nil
+}
+
+// Decline 接收方拒绝等待接受的转移
+func (s *transferService) Decline(ctx context.Context, userID, transferID uint) (*models.FileTransfer, error) {
+	transfer, err := s.getVisible(ctx, userID, transferID)
+	if err != nil {
+		return nil, err
+	}
+	if transfer.ToUserID != userID {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, "只有接收方可以拒绝转移")
+	}
+	return s.close(ctx, transfer, []string{models.TransferStatusPending}, models.TransferStatusDeclined)
+}
+
+// Cancel 发起方取消尚未完成的转移
+func (s *transferService) Cancel(ctx context.Context, userID, transferID uint) (*models.FileTransfer, error) {
+	transfer, err := s.getVisible(ctx, userID, transferID)
+	if err != nil {
+		return nil, err
+	}
+	if transfer.FromUserID != userID {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, "只有发起方可以取消转移")
+	}
+	return s.close(ctx, transfer, []string{models.TransferStatusPending, models.TransferStatusAccepted}, models.TransferStatusCancelled)
+}
+
+// Get 查询用户发起的或转给用户的转移
+func (s *transferService) Get(ctx context.Context, userID, transferID uint) (*models.FileTransfer, error) {
+	return s.getVisible(ctx, userID, transferID)
+}
+
+// List 按创建时间倒序分页查询用户的转移记录，direction 和 status 为空时不筛选
+func (s *transferService) List(ctx context.Context, userID uint, direction, status string, page, pageSize int) ([]*models.FileTransfer, int64, error) {
+	if userID == 0 {
+		return nil, 0, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "用户ID不能为空")
+	}
+	switch direction {
+	case "", filerepo.TransferDirectionIncoming, filerepo.TransferDirectionOutgoing:
+	default:
+		return nil, 0, pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "direction只能为incoming或outgoing")
+	}
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 20
+	}
+
+	transfers, total, err := s.transfers.ListByUser(ctx, userID, direction, status, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询转移记录失败: %w", err)
+	}
+	return transfers, total, nil
+}
+
+// ForceTransfer 管理员强制转移文件所有权，用于用户离职交接
+//
+// 指定文件时把该文件或文件夹直接转给接收方；未指定文件时在接收方根目录下创建以原所有者命名的文件夹，
+// 把原所有者根目录下的全部文件逐个转入，单个文件失败不影响其他文件
+func (s *transferService) ForceTransfer(ctx context.Context, adminID uint, req *ForceTransferRequest) (*ForceTransferResult, error) {
+	if adminID == 0 || req == nil || req.FromUserID == 0 || req.ToUserID == 0 {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "管理员ID、原所有者和接收方不能为空")
+	}
+	if req.FromUserID == req.ToUserID {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "原所有者和接收方不能相同")
+	}
+	shareMode, err := normalizeShareMode(req.ShareMode)
+	if err != nil {
+		return nil, err
+	}
+	reason, err := normalizeTransferMessage(req.Reason, true)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.recipient(ctx, req.ToUserID); err != nil {
+		return nil, err
+	}
+
+	result := &ForceTransferResult{Transfers: []*models.FileTransfer{}}
+	var roots []*models.File
+	parentPath := "/"
+	var parentID *uint
+	if req.FileID != 0 {
+		root, err := s.getFile(ctx, req.FileID)
+		if err != nil {
+			return nil, err
+		}
+		if root.UserID != req.FromUserID {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "文件不属于指定的原所有者")
+		}
+		if err := s.checkTransferable(ctx, root); err != nil {
+			return nil, err
+		}
+		roots = []*models.File{root}
+	} else {
+		roots, err = s.listRootFiles(ctx, req.FromUserID)
+		if err != nil {
+			return nil, err
+		}
+		if len(roots) == 0 {
+			return result, nil
+		}
+		folder, err := s.createHandoverFolder(ctx, req.FromUserID, req.ToUserID)
+		if err != nil {
+			return nil, err
+		}
+		result.Folder = folder
+		parentID, parentPath = &folder.ID, folder.GetFullPath()
+	}
+
+	for _, root := range roots {
+		now := s.now()
+		forcedBy := adminID
+		transfer := &models.FileTransfer{
+			FileID:      root.ID,
+			FileName:    root.Name,
+			IsFolder:    root.IsFolder,
+			FromUserID:  req.FromUserID,
+			ToUserID:    req.ToUserID,
+			Status:      models.TransferStatusAccepted,
+			ShareMode:   shareMode,
+			Message:     reason,
+			ForcedBy:    &forcedBy,
+			RespondedAt: &now,
+		}
+		if err := s.transfers.Create(ctx, transfer); err != nil {
+			return nil, fmt.Errorf("创建转移失败: %w", err)
+		}
+		result.Transfers = append(result.Transfers, transfer)
+
+		if err := s.complete(ctx, transfer, parentID, parentPath, false); err != nil {
+			if req.FileID != 0 {
+				return nil, err
+			}
+			result.Failed++
+			continue
+		}
+		result.Completed++
+	}
+
+	s.logger.Info("Files force transferred",
+		zap.Uint("admin_id", adminID),
+		zap.Uint("from_user_id", req.FromUserID),
+		zap.Uint("to_user_id", req.ToUserID),
+		zap.Int("completed", result.Completed),
+		zap.Int("failed", result.Failed))
+	return result, nil
+}
+
+// ExpirePending 把超过有效期仍未接受的转移标记为过期，返回处理的转移数
+func (s *transferService) ExpirePending(ctx context.Context, limit int) (int64, error) {
+	expired, err := s.transfers.ExpirePending(ctx, s.now(), limit)
+	if err != nil {
+		return expired, fmt.Errorf("标记过期转移失败: %w", err)
+	}
+	if expired > 0 {
+		s.logger.Info("Pending file transfers expired", zap.Int64("transfers", expired))
+	}
+	return expired, nil
+}
+
+// complete 把转移的文件及全部子项移到接收方的 parentID 文件夹下(nil为根目录)并转移存储用量
+//
+// checkQuota 为true时接收方剩余空间不足则失败；失败时记录原因，转移保持已接受状态
+func (s *transferService) complete(ctx context.Context, transfer *models.FileTransfer, parentID *uint, parentPath string, checkQuota bool) error {
+	err := s.move(ctx, transfer, parentID, parentPath, checkQuota)
+	if err == nil {
+		return nil
+	}
+
+	s.logger.Warn("Failed to complete file transfer",
+		zap.Uint("transfer_id", transfer.ID),
+		zap.Uint("file_id", transfer.FileID),
+		zap.Error(err))
+	message := err.Error()
+	if utf8.RuneCountInString(message) > maxTransferMessageLength {
+		message = string([]rune(message)[:maxTransferMessageLength])
+	}
+	if recordErr := s.transfers.RecordFailure(ctx, transfer.ID, message); recordErr != nil {
+		s.logger.Warn("Failed to record file transfer failure",
+			zap.Uint("transfer_id", transfer.ID),
+			zap.Error(recordErr))
+	}
+	transfer.Error = &message
+	return err
+}
+
+// move 执行转移：校验文件，计算新位置和存储用量，在一个事务中写入后调整双方的存储用量
+func (s *transferService) move(ctx context.Context, transfer *models.FileTransfer, parentID *uint, parentPath string, checkQuota bool) error {
+	root, err := s.getFile(ctx, transfer.FileID)
+	if err != nil {
+		return err
+	}
+	if root.UserID != transfer.FromUserID {
+		return pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "文件已不属于发起方")
+	}
+	if !root.IsActive() {
+		return pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "文件当前不可转移")
+	}
+	if _, err := s.recipient(ctx, transfer.ToUserID); err != nil {
+		return err
+	}
+
+	subtree, err := s.folderRepo.ListSubtree(ctx, root)
+	if err != nil {
+		return fmt.Errorf("获取文件夹内容失败: %w", err)
+	}
+	if err := s.checkSubtree(subtree); err != nil {
+		return err
+	}
+	size, err := s.usage(ctx, subtree)
+	if err != nil {
+		return err
+	}
+	if checkQuota && size > 0 {
+		recipient, err := s.accounts.GetByID(ctx, transfer.ToUserID)
+		if err != nil {
+			return fmt.Errorf("获取接收方失败: %w", err)
+		}
+		if recipient.StorageQuota > 0 && !recipient.HasStorageSpace(size) {
+			return pkgErrors.WrapError(pkgErrors.ErrQuotaExceeded, "接收方存储空间不足")
+		}
+	}
+	if err := checkFolderLimits(ctx, s.folders, transfer.ToUserID, parentID, parentPath, subtreeHeight(root, subtree)); err != nil {
+		return err
+	}
+
+	name, ok, err := nextAvailableName(root.Name, root.IsFolder, func(candidate string) (bool, error) {
+		return s.folderRepo.NameExists(ctx, transfer.ToUserID, parentID, candidate, 0)
+	})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return pkgErrors.WrapError(pkgErrors.ErrResourceExists, "接收方同名文件过多，请先重命名后再转移")
+	}
+
+	// 写入前使原父链上的校验和失效，转移后原父链不再包含这些文件
+	s.invalidateChecksums(ctx, root.ID)
+
+	oldPath := root.GetFullPath()
+	root.UserID, root.Name, root.ParentID, root.Path = transfer.ToUserID, name, parentID, parentPath
+	if !root.IsFolder {
+		root.Extension = fileExtension(name)
+	}
+	newPath := root.GetFullPath()
+	descendants := make([]*models.File, 0, len(subtree))
+	for _, file := range subtree {
+		if file.ID == root.ID {
+			continue
+		}
+		file.UserID = transfer.ToUserID
+		file.Path = newPath + strings.TrimPrefix(file.Path, oldPath)
+		descendants = append(descendants, file)
+	}
+
+	completedAt := s.now()
+	transfer.FileName, transfer.Items, transfer.Size, transfer.CompletedAt = name, int64(len(subtree)), size, &completedAt
+	if err := s.transfers.Complete(ctx, transfer, root, descendants); err != nil {
+		if errors.Is(err, filerepo.ErrTransferStateChanged) {
+			return pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "转移状态或文件所有者已变更，请刷新后重试")
+		}
+		return fmt.Errorf("转移文件失败: %w", err)
+	}
+	transfer.Status, transfer.Error = models.TransferStatusCompleted, nil
+
+	if size > 0 {
+		s.updateStorageUsed(ctx, transfer.FromUserID, -size)
+		s.updateStorageUsed(ctx, transfer.ToUserID, size)
+	}
+	s.invalidateChecksums(ctx, root.ID)
+	ids := make([]uint, 0, len(subtree))
+	for _, file := range subtree {
+		ids = append(ids, file.ID)
+	}
+	s.evictCache(ids)
+
+	s.logger.Info("File transfer completed",
+		zap.Uint("transfer_id", transfer.ID),
+		zap.Uint("file_id", root.ID),
+		zap.Uint("from_user_id", transfer.FromUserID),
+		zap.Uint("to_user_id", transfer.ToUserID),
+		zap.Int64("items", transfer.Items),
+		zap.Int64("size", size),
+		zap.String("share_mode", transfer.ShareMode))
+	return nil
+}
+
+// close 把转移改为拒绝或取消状态
+func (s *transferService) close(ctx context.Context, transfer *models.FileTransfer, from []string, to string) (*models.FileTransfer, error) {
+	now := s.now()
+	ok, err := s.transfers.Respond(ctx, transfer.ID, from, to, now)
+	if err != nil {
+		return nil, fmt.Errorf("更新转移状态失败: %w", err)
+	}
+	if !ok {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "转移已完成或已结束")
+	}
+	transfer.Status, transfer.RespondedAt = to, &now
+
+	s.logger.Info("File transfer closed",
+		zap.Uint("transfer_id", transfer.ID),
+		zap.String("status", to))
+	return transfer, nil
+}
+
+// checkTransferable 检查文件可以发起转移：可用、子项数不超过上限、没有正在上传的文件，
+// 且文件本身、上级文件夹和子项都不在进行中的转移里
+func (s *transferService) checkTransferable(ctx context.Context, root *models.File) error {
+	if !root.IsActive() {
+		return pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "文件当前不可转移")
+	}
+	subtree, err := s.folderRepo.ListSubtree(ctx, root)
+	if err != nil {
+		return fmt.Errorf("获取文件夹内容失败: %w", err)
+	}
+	if err := s.checkSubtree(subtree); err != nil {
+		return err
+	}
+
+	ids := make([]uint, 0, len(subtree))
+	for _, file := range subtree {
+		ids = append(ids, file.ID)
+	}
+	current := root
+	for depth := 0; current.ParentID != nil && depth < maxFolderDepth; depth++ {
+		ids = append(ids, *current.ParentID)
+		if current, err = s.fileRepo.GetByID(ctx, *current.ParentID); err != nil {
+			return fmt.Errorf("获取上级文件夹失败: %w", err)
+		}
+	}
+	open, err := s.transfers.ListOpenByFiles(ctx, ids)
+	if err != nil {
+		return fmt.Errorf("查询进行中的转移失败: %w", err)
+	}
+	if len(open) > 0 {
+		return pkgErrors.WrapError(pkgErrors.ErrResourceExists, "文件或其所在文件夹正在转移中")
+	}
+	return nil
+}
+
+// checkSubtree 检查子树的文件数和上传状态
+func (s *transferService) checkSubtree(subtree []*models.File) error {
+	if len(subtree) > s.options.MaxItems {
+		return pkgErrors.WrapErrorf(pkgErrors.ErrOperationNotAllowed, "单次最多转移 %d 个文件", s.options.MaxItems)
+	}
+	for _, file := range subtree {
+		if file.Status == models.FileStatusUploading {
+			return pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "文件夹中有正在上传的文件，请上传完成后再转移")
+		}
+	}
+	return nil
+}
+
+// usage 计算子树占用的存储用量，含历史版本
+func (s *transferService) usage(ctx context.Context, subtree []*models.File) (int64, error) {
+	var size int64
+	var fileIDs []uint
+	for _, file := range subtree {
+		if file.IsFolder || !file.IsActive() {
+			continue
+		}
+		size += file.Size
+		fileIDs = append(fileIDs, file.ID)
+	}
+	if s.versions == nil || len(fileIDs) == 0 {
+		return size, nil
+	}
+	versions, err := s.versions.ListByFiles(ctx, fileIDs)
+	if err != nil {
+		return 0, fmt.Errorf("获取历史版本失败: %w", err)
+	}
+	for _, version := range versions {
+		size += version.Size
+	}
+	return size, nil
+}
+
+// listRootFiles 列出用户根目录下的全部可用文件
+func (s *transferService) listRootFiles(ctx context.Context, userID uint) ([]*models.File, error) {
+	entries, err := s.folderRepo.ListContentNames(ctx, userID, nil, filerepo.ContentsFilter{}, s.options.MaxItems+1)
+	if err != nil {
+		return nil, fmt.Errorf("获取根目录文件失败: %w", err)
+	}
+	if len(entries) > s.options.MaxItems {
+		return nil, pkgErrors.WrapErrorf(pkgErrors.ErrOperationNotAllowed, "根目录下超过 %d 个文件，请分批转移", s.options.MaxItems)
+	}
+	ids := make([]uint, 0, len(entries))
+	for _, entry := range entries {
+		ids = append(ids, entry.ID)
+	}
+	roots, err := s.folderRepo.FindContents(ctx, userID, ids, false)
+	if err != nil {
+		return nil, fmt.Errorf("获取根目录文件失败: %w", err)
+	}
+	return roots, nil
+}
+
+// createHandoverFolder 在接收方根目录下创建以原所有者命名的文件夹，同名时自动追加序号
+func (s *transferService) createHandoverFolder(ctx context.Context, fromUserID, toUserID uint) (*models.File, error) {
+	owner, err := s.accounts.GetByID(ctx, fromUserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "原所有者不存在")
+		}
+		return nil, fmt.Errorf("获取原所有者失败: %w", err)
+	}
+	base, err := utils.NormalizeFileName(owner.Username + " 的文件")
+	if err != nil {
+		base = "用户" + strconv.FormatUint(uint64(fromUserID), 10) + " 的文件"
+	}
+	if err := checkFolderLimits(ctx, s.folders, toUserID, nil, "/", 1); err != nil {
+		return nil, err
+	}
+	name, ok, err := nextAvailableName(base, true, func(candidate string) (bool, error) {
+		return s.folderRepo.NameExists(ctx, toUserID, nil, candidate, 0)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrResourceExists, "接收方同名文件夹过多，请先重命名后再转移")
+	}
+
+	folder := &models.File{
+		UserID:       toUserID,
+		Name:         name,
+		Path:         "/",
+		IsFolder:     true,
+		StorageClass: storage.StorageClassStandard,
+		AccessLevel:  "private",
+		Status:       "active",
+		UploadStatus: "completed",
+	}
+	if err := s.fileRepo.Create(ctx, folder); err != nil {
+		return nil, fmt.Errorf("创建文件夹失败: %w", err)
+	}
+	return folder, nil
+}
+
+// recipient 获取接收方并检查其账户状态
+func (s *transferService) recipient(ctx context.Context, userID uint) (*models.User, error) {
+	user, err := s.accounts.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "接收方不存在")
+		}
+		return nil, fmt.Errorf("获取接收方失败: %w", err)
+	}
+	if !user.IsActive() {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "接收方账户不可用")
+	}
+	return user, nil
+}
+
+// getFile 获取未删除的文件
+func (s *transferService) getFile(ctx context.Context, fileID uint) (*models.File, error) {
+	file, err := s.fileRepo.GetByID(ctx, fileID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "文件不存在")
+		}
+		return nil, fmt.Errorf("获取文件失败: %w", err)
+	}
+	return file, nil
+}
+
+// getVisible 获取用户发起的或转给用户的转移，其他用户视为不存在
+func (s *transferService) getVisible(ctx context.Context, userID, transferID uint) (*models.FileTransfer, error) {
+	if userID == 0 || transferID == 0 {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "用户ID和转移ID不能为空")
+	}
+	transfer, err := s.transfers.GetByID(ctx, transferID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "转移不存在")
+		}
+		return nil, fmt.Errorf("获取转移失败: %w", err)
+	}
+	if transfer.FromUserID != userID && transfer.ToUserID != userID {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "转移不存在")
+	}
+	return transfer, nil
+}
+
+// updateStorageUsed 调整用户的存储用量，失败只记录日志
+func (s *transferService) updateStorageUsed(ctx context.Context, userID uint, delta int64) {
+	if err := s.accounts.UpdateStorageUsed(ctx, userID, delta); err != nil {
+		s.logger.Error("Failed to update storage usage after transfer",
+			zap.Uint("user_id", userID),
+			zap.Int64("size", delta),
+			zap.Error(err))
+	}
+}
+
+// invalidateChecksums 使父链上的文件夹校验和失效，失败只记录日志
+func (s *transferService) invalidateChecksums(ctx context.Context, fileID uint) {
+	if s.checksums == nil {
+		return
+	}
+	if err := s.checksums.InvalidateChecksums(ctx, fileID); err != nil {
+		s.logger.Warn("Failed to invalidate folder checksums",
+			zap.Uint("file_id", fileID),
+			zap.Error(err))
+	}
+}
+
+// evictCache 清除文件的信息、预览和下载缓存，失败只记录日志
+func (s *transferService) evictCache(ids []uint) {
+	if s.cache == nil || len(ids) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(ids)*3)
+	for _, id := range ids {
+		fileID := strconv.FormatUint(uint64(id), 10)
+		keys = append(keys, s.keys.FileInfo(fileID), s.keys.FilePreview(fileID), s.keys.FileDownload(fileID))
+	}
+	if err := s.cache.Delete(keys...); err != nil {
+		s.logger.Warn("Failed to evict file cache",
+			zap.Int("files", len(ids)),
+			zap.Error(err))
+	}
+}
+
+// normalizeShareMode 校验分享处理方式，为空时保留分享
+func normalizeShareMode(mode string) (string, error) {
+	if mode == "" {
+		return models.TransferShareKeep, nil
+	}
+	if !models.IsValidTransferShareMode(mode) {
+		return "", pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "share_mode只能为keep或revoke")
+	}
+	return mode, nil
+}
+
+// normalizeTransferMessage 去除首尾空白并校验长度，required 为false时空留言返回nil
+func normalizeTransferMessage(message string, required bool) (*string, error) {
+	message = strings.TrimSpace(message)
+	if message == "" {
+		if required {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "请填写转移原因")
+		}
+		return nil, nil
+	}
+	if utf8.RuneCountInString(message) > maxTransferMessageLength {
+		return nil, pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "留言最多%d个字符", maxTransferMessageLength)
+	}
+	return &message, nil
+}
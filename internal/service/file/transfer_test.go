@@ -0,0 +1,290 @@
+package file
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	filerepo "cloudpan/internal/repository/file"
+	"cloudpan/internal/repository/models"
+)
+
+// memoryTransfers 内存文件所有权转移仓储
+type memoryTransfers struct {
+	transfers map[uint]*models.FileTransfer
+	nextID    uint
+	completed [][]*models.File // 每次完成时写入的子项
+}
+
+func (m *memoryTransfers) Create(_ context.Context, transfer *models.FileTransfer) error {
+	m.nextID++
+	transfer.ID = m.nextID
+	stored := *transfer
+	m.transfers[transfer.ID] = &stored
+	return nil
+}
+
+func (m *memoryTransfers) GetByID(_ context.Context, id uint) (*models.FileTransfer, error) {
+	if transfer, ok := m.transfers[id]; ok {
+		copied := *transfer
+		return &copied, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *memoryTransfers) ListByUser(_ context.Context, userID uint, direction, status string, limit, offset int) ([]*models.FileTransfer, int64, error) {
+	var transfers []*models.FileTransfer
+	for _, transfer := range m.transfers {
+		incoming, outgoing := transfer.ToUserID == userID, transfer.FromUserID == userID
+		if direction == filerepo.TransferDirectionIncoming && !incoming ||
+			direction == filerepo.TransferDirectionOutgoing && !outgoing ||
+			!incoming && !outgoing || status != "" && transfer.Status != status {
+			continue
+		}
+		transfers = append(transfers, transfer)
+	}
+	return transfers, int64(len(transfers)), nil
+}
+
+func (m *memoryTransfers) ListOpenByFiles(_ context.Context, fileIDs []uint) ([]*models.FileTransfer, error) {
+	var transfers []*models.FileTransfer
+	for _, transfer := range m.transfers {
+		for _, id := range fileIDs {
+			if transfer.FileID == id && transfer.IsOpen() {
+				transfers = append(transfers, transfer)
+			}
+		}
+	}
+	return transfers, nil
+}
+
+func (m *memoryTransfers) Respond(_ context.Context, id uint, from []string, to string, at time.Time) (bool, error) {
+	transfer, ok := m.transfers[id]
+	if !ok {
+		return false, nil
+	}
+	for _, status := range from {
+		if transfer.Status == status {
+			transfer.Status, transfer.RespondedAt = to, &at
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *memoryTransfers) RecordFailure(_ context.Context, id uint, message string) error {
+	if transfer, ok := m.transfers[id]; ok && transfer.Status == models.TransferStatusAccepted {
+		transfer.Error = &message
+	}
+	return nil
+}
+
+func (m *memoryTransfers) ExpirePending(_ context.Context, now time.Time, limit int) (int64, error) {
+	var expired int64
+	for _, transfer := range m.transfers {
+		if expired < int64(limit) && transfer.Status == models.TransferStatusPending && transfer.ExpiresAt.Before(now) {
+			transfer.Status = models.TransferStatusExpired
+			expired++
+		}
+	}
+	return expired, nil
+}
+
+// Complete 文件由服务直接修改，内存中只更新转移状态
+func (m *memoryTransfers) Complete(_ context.Context, transfer *models.FileTransfer, _ *models.File, descendants []*models.File) error {
+	stored := m.transfers[transfer.ID]
+	if stored == nil || stored.Status != models.TransferStatusAccepted {
+		return filerepo.ErrTransferStateChanged
+	}
+	stored.Status, stored.Error = models.TransferStatusCompleted, nil
+	stored.FileName, stored.Items, stored.Size, stored.CompletedAt = transfer.FileName, transfer.Items, transfer.Size, transfer.CompletedAt
+	m.completed = append(m.completed, descendants)
+	return nil
+}
+
+type transferFixture struct {
+	*treeFixture
+	service   *transferService
+	transfers *memoryTransfers
+	versions  *memoryVersions
+	users     map[uint]*models.User
+}
+
+// newTransferFixture 在 newTreeFixture 的目录树(属于用户7)基础上创建接收方用户8，用户8的根目录下已有 docs(50)
+func newTransferFixture(t *testing.T) *transferFixture {
+	tree := newTreeFixture(t)
+	existing := newTestFile(50, 8, nil, "docs", true)
+	existing.Path, existing.Status = "/", "active"
+	tree.folders.files[existing.ID] = existing
+
+	users := map[uint]*models.User{
+		7: {Username: "alice", Status: "active"},
+		8: {Username: "bob", Status: "active", StorageQuota: 100, StorageUsed: 80},
+		9: {Username: "carol", Status: "suspended"},
+	}
+	for id, user := range users {
+		user.ID = id
+		tree.accounts.On("GetByID", mock.Anything, id).Return(user, nil).Maybe()
+	}
+	tree.accounts.On("GetByID", mock.Anything, mock.Anything).Return(nil, gorm.ErrRecordNotFound).Maybe()
+
+	transfers := &memoryTransfers{transfers: make(map[uint]*models.FileTransfer)}
+	versions := &memoryVersions{versions: []*models.FileVersion{{FileID: 2, VersionNumber: 1, Size: 2}}}
+	service := NewTransferService(tree.folders, tree.folders, transfers, tree.accounts, versions, nil, tree.cache, nil, TransferOptions{}, nil).(*transferService)
+	service.now = tree.service.now
+	return &transferFixture{treeFixture: tree, service: service, transfers: transfers, versions: versions, users: users}
+}
+
+func TestTransferService_InitiateAndAccept(t *testing.T) {
+	ctx := context.Background()
+	f := newTransferFixture(t)
+
+	// 接收方必须存在且账户可用，不能转给自己，只能转移自己的文件
+	_, err := f.service.Initiate(ctx, 7, &TransferRequest{FileID: 1, ToUserID: 7})
+	assert.True(t, pkgErrors.IsValidationError(err))
+	_, err = f.service.Initiate(ctx, 7, &TransferRequest{FileID: 1, ToUserID: 9})
+	assert.ErrorIs(t, err, pkgErrors.ErrOperationNotAllowed)
+	_, err = f.service.Initiate(ctx, 8, &TransferRequest{FileID: 1, ToUserID: 7})
+	assert.True(t, pkgErrors.IsPermissionError(err))
+
+	transfer, err := f.service.Initiate(ctx, 7, &TransferRequest{FileID: 1, ToUserID: 8, Message: " 项目资料 "})
+	require.NoError(t, err)
+	assert.Equal(t, models.TransferStatusPending, transfer.Status)
+	assert.Equal(t, models.TransferShareKeep, transfer.ShareMode)
+	assert.Equal(t, "项目资料", *transfer.Message)
+	assert.Equal(t, f.service.now().Add(DefaultTransferExpiry), *transfer.ExpiresAt)
+
+	// 转移进行中的文件夹的子项不能再次发起转移
+	_, err = f.service.Initiate(ctx, 7, &TransferRequest{FileID: 4, ToUserID: 8})
+	assert.ErrorIs(t, err, pkgErrors.ErrResourceExists)
+
+	// 只有接收方可以接受；存储用量含历史版本，超出接收方配额时转移保持已接受状态
+	_, err = f.service.Accept(ctx, 7, transfer.ID)
+	assert.True(t, pkgErrors.IsPermissionError(err))
+	f.users[8].StorageUsed = 95
+	_, err = f.service.Accept(ctx, 8, transfer.ID)
+	assert.ErrorIs(t, err, pkgErrors.ErrQuotaExceeded)
+	stored := f.transfers.transfers[transfer.ID]
+	assert.Equal(t, models.TransferStatusAccepted, stored.Status)
+	require.NotNil(t, stored.Error)
+	assert.Equal(t, uint(7), f.folders.files[1].UserID)
+
+	// 配额调整后重试完成，同名时自动重命名
+	f.users[8].StorageUsed = 80
+	f.accounts.On("UpdateStorageUsed", mock.Anything, uint(7), int64(-10)).Return(nil).Once()
+	f.accounts.On("UpdateStorageUsed", mock.Anything, uint(8), int64(10)).Return(nil).Once()
+	completed, err := f.service.Complete(ctx, 7, transfer.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.TransferStatusCompleted, completed.Status)
+	assert.Equal(t, "docs (1)", completed.FileName)
+	assert.Equal(t, int64(4), completed.Items)
+	assert.Equal(t, int64(10), completed.Size)
+	assert.Nil(t, f.transfers.transfers[transfer.ID].Error)
+	f.accounts.AssertExpectations(t)
+
+	for _, id := range []uint{1, 2, 3, 4} {
+		assert.Equal(t, uint(8), f.folders.files[id].UserID)
+	}
+	assert.Nil(t, f.folders.files[1].ParentID)
+	assert.Equal(t, "/docs (1)/sub", f.folders.files[4].Path)
+	assert.Len(t, f.transfers.completed[0], 3)
+	assert.Contains(t, f.cache.keys, "file:4")
+
+	// 已完成的转移不能再取消
+	_, err = f.service.Cancel(ctx, 7, transfer.ID)
+	assert.ErrorIs(t, err, pkgErrors.ErrOperationNotAllowed)
+}
+
+func TestTransferService_DeclineCancelAndExpire(t *testing.T) {
+	ctx := context.Background()
+	f := newTransferFixture(t)
+
+	declined, err := f.service.Initiate(ctx, 7, &TransferRequest{FileID: 2, ToUserID: 8, ShareMode: models.TransferShareRevoke})
+	require.NoError(t, err)
+	_, err = f.service.Decline(ctx, 7, declined.ID)
+	assert.True(t, pkgErrors.IsPermissionError(err))
+	declined, err = f.service.Decline(ctx, 8, declined.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.TransferStatusDeclined, declined.Status)
+
+	// 其他用户看不到转移
+	_, err = f.service.Get(ctx, 9, declined.ID)
+	assert.True(t, pkgErrors.IsNotFoundError(err))
+
+	cancelled, err := f.service.Initiate(ctx, 7, &TransferRequest{FileID: 2, ToUserID: 8})
+	require.NoError(t, err)
+	_, err = f.service.Cancel(ctx, 8, cancelled.ID)
+	assert.True(t, pkgErrors.IsPermissionError(err))
+	cancelled, err = f.service.Cancel(ctx, 7, cancelled.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.TransferStatusCancelled, cancelled.Status)
+
+	expiring, err := f.service.Initiate(ctx, 7, &TransferRequest{FileID: 5, ToUserID: 8})
+	require.NoError(t, err)
+	f.service.now = func() time.Time { return expiring.ExpiresAt.Add(time.Second) }
+	_, err = f.service.Accept(ctx, 8, expiring.ID)
+	assert.ErrorIs(t, err, pkgErrors.ErrOperationNotAllowed)
+	expired, err := f.service.ExpirePending(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), expired)
+
+	transfers, total, err := f.service.List(ctx, 8, filerepo.TransferDirectionIncoming, models.TransferStatusExpired, 1, 20)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Equal(t, expiring.ID, transfers[0].ID)
+	_, _, err = f.service.List(ctx, 8, "sideways", "", 1, 20)
+	assert.True(t, pkgErrors.IsValidationError(err))
+}
+
+func TestTransferService_ForceTransfer(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("all files into handover folder", func(t *testing.T) {
+		f := newTransferFixture(t)
+		// 强制转移不检查接收方配额
+		f.users[8].StorageUsed = 100
+		f.accounts.On("UpdateStorageUsed", mock.Anything, uint(7), int64(-10)).Return(nil).Once()
+		f.accounts.On("UpdateStorageUsed", mock.Anything, uint(8), int64(10)).Return(nil).Once()
+
+		_, err := f.service.ForceTransfer(ctx, 1, &ForceTransferRequest{FromUserID: 7, ToUserID: 8})
+		assert.True(t, pkgErrors.IsValidationError(err))
+
+		result, err := f.service.ForceTransfer(ctx, 1, &ForceTransferRequest{FromUserID: 7, ToUserID: 8, Reason: "离职交接"})
+		require.NoError(t, err)
+		require.NotNil(t, result.Folder)
+		assert.Equal(t, "alice 的文件", result.Folder.Name)
+		assert.Equal(t, uint(8), result.Folder.UserID)
+		assert.Equal(t, 2, result.Completed)
+		assert.Zero(t, result.Failed)
+		require.Len(t, result.Transfers, 2)
+		for _, transfer := range result.Transfers {
+			assert.Equal(t, models.TransferStatusCompleted, transfer.Status)
+			require.NotNil(t, transfer.ForcedBy)
+			assert.Equal(t, uint(1), *transfer.ForcedBy)
+		}
+
+		assert.Equal(t, result.Folder.ID, *f.folders.files[1].ParentID)
+		assert.Equal(t, "/alice 的文件/docs/sub", f.folders.files[4].Path)
+		assert.Equal(t, uint(8), f.folders.files[5].UserID)
+		f.accounts.AssertExpectations(t)
+	})
+
+	t.Run("single file must belong to owner", func(t *testing.T) {
+		f := newTransferFixture(t)
+		_, err := f.service.ForceTransfer(ctx, 1, &ForceTransferRequest{FromUserID: 8, ToUserID: 7, FileID: 2, Reason: "离职交接"})
+		assert.True(t, pkgErrors.IsValidationError(err))
+
+		result, err := f.service.ForceTransfer(ctx, 1, &ForceTransferRequest{FromUserID: 7, ToUserID: 8, FileID: 5, Reason: "离职交接"})
+		require.NoError(t, err)
+		assert.Nil(t, result.Folder)
+		assert.Equal(t, 1, result.Completed)
+		assert.Equal(t, "archive", result.Transfers[0].FileName)
+		assert.Equal(t, uint(8), f.folders.files[5].UserID)
+	})
+}
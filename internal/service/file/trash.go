@@ -0,0 +1,618 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/config"
+	basemodels "cloudpan/internal/pkg/database/models"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	filerepo "cloudpan/internal/repository/file"
+	"cloudpan/internal/repository/models"
+)
+
+// 回收站默认值
+const (
+	DefaultTrashRetention           = 30 * 24 * time.Hour
+	DefaultTrashMinRetention        = 24 * time.Hour
+	DefaultEmptyTrashAsyncThreshold = 100
+
+	// maxNameAttempts 同名冲突时的最大重命名尝试次数
+	maxNameAttempts = 100
+)
+
+// trashMetaFileIDs 回收站项目元数据键，保存本次移入回收站的文件ID
+//
+// 文件夹内此前单独删除的子项有自己的回收站项目，恢复文件夹时不随之恢复
+const trashMetaFileIDs = "file_ids"
+
+// TrashService 回收站服务接口
+//
+// 1. 移入回收站：删除文件或文件夹时连同全部子项软删除，存储用量在彻底删除前保持占用
+// 2. 查询：分页列出用户回收站中的项目及其自动清理时间
+// 3. 恢复：恢复到原文件夹，原文件夹已不存在时恢复到根目录；同名冲突时自动重命名
+// 4. 彻底删除：删除文件记录并释放存储配额，存储对象立即删除或按保留策略交给 ContentRetainer 保留，历史版本一并删除
+// 5. 自动清理：超过保留期的项目由维护任务定期彻底删除
+// 6. 保留时长设置：用户可以在管理员设置的范围内自定义保留天数，对之后移入回收站的项目生效
+// 7. 清空回收站：先获取确认令牌，凭令牌清空确认时回收站中的项目，项目较多时在后台执行
+//
+// 使用示例：
+//
+//	service := NewTrashService(fileRepo, trashRepo, userRepo, store, fileService, retention, versionService, userRepo, TrashOptionsFromConfig(cfg.Storage.Trash), logger)
+//	item, err := service.MoveToTrash(ctx, userID, fileID)
+//	restored, err := service.Restore(ctx, userID, item.ID)
+//	purged, err := service.PurgeExpired(ctx, 100)
+//	confirmation, err := service.PrepareEmpty(ctx, userID)
+//	job, err := service.EmptyTrash(ctx, userID, confirmation.Token)
+type TrashService interface {
+	MoveToTrash(ctx context.Context, userID, fileID uint) (*TrashItem, error)
+	ListTrash(ctx context.Context, userID uint, page, pageSize int) ([]*TrashItem, int64, error)
+	Restore(ctx context.Context, userID, itemID uint) (*RestoredFile, error)
+	DeletePermanently(ctx context.Context, userID, itemID uint) error
+	PurgeExpired(ctx context.Context, limit int) (int64, error)
+
+	GetSettings(ctx context.Context, userID uint) (*TrashSettings, error)
+	UpdateSettings(ctx context.Context, userID uint, retentionDays int) (*TrashSettings, error)
+
+	PrepareEmpty(ctx context.Context, userID uint) (*EmptyTrashConfirmation, error)
+	EmptyTrash(ctx context.Context, userID uint, token string) (*EmptyTrashJob, error)
+	GetEmptyJob(ctx context.Context, userID uint, jobID string) (*EmptyTrashJob, error)
+}
+
+// ObjectDeleter 存储对象删除，由 storage.Storage 实现
+type ObjectDeleter interface {
+	Delete(ctx context.Context, path string) error
+}
+
+// ChecksumInvalidator 文件夹校验和失效，由 FileService 实现
+type ChecksumInvalidator interface {
+	InvalidateChecksums(ctx context.Context, fileID uint) error
+}
+
+// TrashOptions 回收站选项
+type TrashOptions struct {
+	Retention           time.Duration // 回收站默认保留时长，过期后自动彻底删除
+	MinRetention        time.Duration // 用户可自定义的最短保留时长
+	MaxRetention        time.Duration // 用户可自定义的最长保留时长
+	EmptyAsyncThreshold int           // 清空回收站时项目数超过该值在后台执行
+}
+
+// TrashOptionsFromConfig 从回收站配置生成选项
+func TrashOptionsFromConfig(cfg config.TrashConfig) TrashOptions {
+	return TrashOptions{
+		Retention:           cfg.Retention,
+		MinRetention:        cfg.MinRetention,
+		MaxRetention:        cfg.MaxRetention,
+		EmptyAsyncThreshold: cfg.EmptyAsyncThreshold,
+	}
+}
+
+// TrashItem 回收站项目
+type TrashItem struct {
+	ID           uint      `json:"id"`             // 回收站项目ID
+	FileID       uint      `json:"file_id"`        // 文件ID
+	Name         string    `json:"name"`           // 文件名
+	OriginalPath string    `json:"original_path"`  // 删除前的完整路径
+	IsFolder     bool      `json:"is_folder"`      // 是否为文件夹
+	Size         int64     `json:"size"`           // 文件或文件夹内文件的总大小
+	TrashedAt    time.Time `json:"trashed_at"`     // 移入回收站的时间
+	AutoDeleteAt time.Time `json:"auto_delete_at"` // 自动彻底删除的时间
+}
+
+// RestoredFile 恢复结果
+type RestoredFile struct {
+	FileID    uint   `json:"file_id"`   // 文件ID
+	Name      string `json:"name"`      // 恢复后的文件名，同名冲突时与原名不同
+	Path      string `json:"path"`      // 恢复后的完整路径
+	Relocated bool   `json:"relocated"` // 原文件夹不存在，已恢复到根目录
+	Renamed   bool   `json:"renamed"`   // 目标位置有同名文件，已自动重命名
+}
+
+// VersionPurger 历史版本清理，由 VersionService 实现
+type VersionPurger interface {
+	PurgeVersions(ctx context.Context, fileIDs []uint) (int64, error)
+}
+
+// TrashAccountStore 用户存储用量更新，由用户仓储实现
+type TrashAccountStore interface {
+	UpdateStorageUsed(ctx context.Context, userID uint, size int64) error
+}
+
+// trashService 回收站服务实现
+type trashService struct {
+	fileRepo  filerepo.FileRepository
+	trashRepo filerepo.TrashRepository
+	accounts  TrashAccountStore
+	store     ObjectDeleter
+	checksums ChecksumInvalidator
+	retainer  ContentRetainer
+	versions  VersionPurger
+	prefs     TrashPreferenceStore
+	options   TrashOptions
+	logger    *zap.Logger
+	now       func() time.Time
+
+	// 清空回收站的确认令牌和任务进度保存在当前实例内存中
+	mu            sync.Mutex
+	emptyTokens   map[string]*emptyTrashToken
+	emptyJobs     map[string]*EmptyTrashJob
+	emptyAsyncRun func(func())
+}
+
+// NewTrashService 创建回收站服务，checksums、retainer、versions 和 prefs 可以为nil，retainer为nil时彻底删除立即删除存储对象，
+// versions为nil时不清理历史版本，prefs为nil时用户不能自定义保留时长
+func NewTrashService(fileRepo filerepo.FileRepository, trashRepo filerepo.TrashRepository, accounts TrashAccountStore,
+	store ObjectDeleter, checksums ChecksumInvalidator, retainer ContentRetainer, versions VersionPurger, prefs TrashPreferenceStore,
+	options TrashOptions, logger *zap.Logger) TrashService {
+	if options.Retention <= 0 {
+		options.Retention = DefaultTrashRetention
+	}
+	if options.MinRetention <= 0 {
+		options.MinRetention = DefaultTrashMinRetention
+	}
+	if options.MaxRetention <= 0 {
+		options.MaxRetention = options.Retention
+	}
+	// 默认保留时长始终在用户可选的范围内
+	options.MinRetention = min(options.MinRetention, options.Retention)
+	options.MaxRetention = max(options.MaxRetention, options.Retention)
+	if options.EmptyAsyncThreshold <= 0 {
+		options.EmptyAsyncThreshold = DefaultEmptyTrashAsyncThreshold
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &trashService{
+		fileRepo:  fileRepo,
+		trashRepo: trashRepo,
+		accounts:  accounts,
+		store:     store,
+		checksums: checksums,
+		retainer:  retainer,
+		versions:  versions,
+		prefs:     prefs,
+		options:   options,
+		logger:    logger,
+		now:       time.Now,
+
+		emptyTokens:   make(map[string]*emptyTrashToken),
+		emptyJobs:     make(map[string]*EmptyTrashJob),
+		emptyAsyncRun: func(run func()) { go run() },
+	}
+}
+
+// MoveToTrash 将文件或文件夹连同全部子项移入回收站
+//
+// 文件夹内此前单独删除的子项保留各自的回收站项目
+func (s *trashService) MoveToTrash(ctx context.Context, userID, fileID uint) (*TrashItem, error) {
+	if userID == 0 || fileID == 0 {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "用户ID和文件ID不能为空")
+	}
+
+	file, err := s.fileRepo.GetByID(ctx, fileID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "文件不存在")
+		}
+		return nil, fmt.Errorf("获取文件失败: %w", err)
+	}
+	if file.UserID != userID {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, "无权删除该文件")
+	}
+	if !file.IsActive() {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "文件正在上传或处理中，暂不能删除")
+	}
+
+	subtree, err := s.trashRepo.ListSubtree(ctx, file)
+	if err != nil {
+		return nil, fmt.Errorf("获取文件夹内容失败: %w", err)
+	}
+	var ids []uint
+	var size int64
+	for _, item := range subtree {
+		if item.DeletedAt.Valid {
+			continue
+		}
+		ids = append(ids, item.ID)
+		if !item.IsFolder && item.IsActive() {
+			size += item.Size
+		}
+	}
+
+	// 文件仍可读取时使父链校验和失效，移入回收站后无法再沿父链查找
+	s.invalidateChecksums(ctx, file.ID)
+
+	now := s.now()
+	entry := &models.RecycleBin{
+		UserID:           userID,
+		FileID:           file.ID,
+		OriginalName:     file.Name,
+		OriginalPath:     file.Path,
+		OriginalParentID: file.ParentID,
+		DeletedBy:        userID,
+		TrashedAt:        now,
+		FileSize:         size,
+		IsFolder:         file.IsFolder,
+		AutoDeleteAt:     now.Add(s.retention(ctx, userID)),
+		Metadata:         &basemodels.JSONMap{trashMetaFileIDs: ids},
+	}
+	if err := s.trashRepo.MoveToTrash(ctx, entry, ids); err != nil {
+		return nil, fmt.Errorf("移入回收站失败: %w", err)
+	}
+
+	s.logger.Info("File moved to trash",
+		zap.Uint("user_id", userID),
+		zap.Uint("file_id", file.ID),
+		zap.Uint("trash_id", entry.ID),
+		zap.Int("files", len(ids)),
+		zap.Int64("size", size))
+	return newTrashItem(entry), nil
+}
+
+// ListTrash 分页列出用户回收站中的项目
+func (s *trashService) ListTrash(ctx context.Context, userID uint, page, pageSize int) ([]*TrashItem, int64, error) {
+	if userID == 0 {
+		return nil, 0, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "用户ID不能为空")
+	}
+
+	entries, total, err := s.trashRepo.ListByUser(ctx, userID, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("获取回收站列表失败: %w", err)
+	}
+
+	items := make([]*TrashItem, 0, len(entries))
+	for _, entry := range entries {
+		items = append(items, newTrashItem(entry))
+	}
+	return items, total, nil
+}
+
+// Restore 恢复回收站项目
+//
+// 原文件夹仍存在时恢复到原位置，否则恢复到根目录；目标位置有同名文件时
+// 自动追加序号，如 "报告 (1).pdf"。文件夹的全部子项随之恢复并更新路径
+func (s *trashService) Restore(ctx context.Context, userID, itemID uint) (*RestoredFile, error) {
+	entry, err := s.getOwnedEntry(ctx, userID, itemID)
+	if err != nil {
+		return nil, err
+	}
+	if !s.now().Before(entry.AutoDeleteAt) {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "回收站项目已过保留期，无法恢复")
+	}
+
+	root, err := s.trashRepo.GetFile(ctx, entry.FileID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "文件已被彻底删除")
+		}
+		return nil, fmt.Errorf("获取文件失败: %w", err)
+	}
+	subtree, err := s.trashRepo.ListSubtree(ctx, root)
+	if err != nil {
+		return nil, fmt.Errorf("获取文件夹内容失败: %w", err)
+	}
+	files := selectTrashedFiles(entry, subtree)
+
+	parentID, parentPath := s.restoreTarget(ctx, userID, entry.OriginalParentID)
+	name, err := s.availableName(ctx, userID, parentID, root.Name, root.IsFolder)
+	if err != nil {
+		return nil, err
+	}
+
+	// 根项目的名称和位置可能变化，子项路径按新的完整路径替换前缀
+	oldFullPath := root.GetFullPath()
+	root.Name, root.ParentID, root.Path = name, parentID, parentPath
+	newFullPath := root.GetFullPath()
+	for _, file := range files {
+		switch {
+		case file.ID == root.ID:
+			file.Name, file.ParentID, file.Path = root.Name, root.ParentID, root.Path
+		case file.Path == oldFullPath || strings.HasPrefix(file.Path, oldFullPath+"/"):
+			file.Path = newFullPath + strings.TrimPrefix(file.Path, oldFullPath)
+		}
+	}
+
+	if err := s.trashRepo.Restore(ctx, entry, files, userID, s.now()); err != nil {
+		return nil, fmt.Errorf("恢复文件失败: %w", err)
+	}
+	s.invalidateChecksums(ctx, root.ID)
+
+	result := &RestoredFile{
+		FileID:    root.ID,
+		Name:      name,
+		Path:      newFullPath,
+		Relocated: !sameParent(parentID, entry.OriginalParentID),
+		Renamed:   name != entry.OriginalName,
+	}
+	s.logger.Info("File restored from trash",
+		zap.Uint("user_id", userID),
+		zap.Uint("trash_id", entry.ID),
+		zap.Uint("file_id", root.ID),
+		zap.Bool("relocated", result.Relocated),
+		zap.Bool("renamed", result.Renamed))
+	return result, nil
+}
+
+// DeletePermanently 彻底删除回收站项目并释放存储配额
+func (s *trashService) DeletePermanently(ctx context.Context, userID, itemID uint) error {
+	entry, err := s.getOwnedEntry(ctx, userID, itemID)
+	if err != nil {
+		return err
+	}
+	_, err = s.purge(ctx, entry)
+	return err
+}
+
+// PurgeExpired 彻底删除一批超过保留期的回收站项目，返回删除的项目数
+//
+// 单个项目失败不影响其他项目，失败的项目在下次清理时重试
+func (s *trashService) PurgeExpired(ctx context.Context, limit int) (int64, error) {
+	entries, err := s.trashRepo.ListExpired(ctx, s.now(), limit)
+	if err != nil {
+		return 0, fmt.Errorf("查询过期回收站项目失败: %w", err)
+	}
+
+	var purged int64
+	var failed error
+	for _, entry := range entries {
+		if ctx.Err() != nil {
+			return purged, ctx.Err()
+		}
+		if _, err := s.purge(ctx, entry); err != nil {
+			failed = err
+			s.logger.Warn("Failed to purge trash item",
+				zap.Uint("trash_id", entry.ID),
+				zap.Uint("file_id", entry.FileID),
+				zap.Error(err))
+			continue
+		}
+		purged++
+	}
+	return purged, failed
+}
+
+// purge 删除或保留项目中文件的存储对象，再物理删除记录并释放存储配额，返回释放的存储空间
+//
+// 存储对象删除和保留登记都是幂等的，记录删除失败时重试不会出错
+func (s *trashService) purge(ctx context.Context, entry *models.RecycleBin) (int64, error) {
+	root, err := s.trashRepo.GetFile(ctx, entry.FileID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, fmt.Errorf("获取文件失败: %w", err)
+	}
+
+	// 文件夹内此前单独删除的子项一并删除，其回收站项目登记的用量随之释放
+	var ids []uint
+	if root != nil {
+		subtree, err := s.trashRepo.ListSubtree(ctx, root)
+		if err != nil {
+			return 0, fmt.Errorf("获取文件夹内容失败: %w", err)
+		}
+		var files []*models.File
+		for _, file := range subtree {
+			if !file.DeletedAt.Valid {
+				continue
+			}
+			files = append(files, file)
+			ids = append(ids, file.ID)
+		}
+		if err := s.releaseContent(ctx, entry.UserID, files); err != nil {
+			return 0, err
+		}
+	}
+	if len(ids) == 0 {
+		ids = []uint{entry.FileID}
+	}
+
+	// 历史版本记录随文件记录级联删除，需要先删除其存储对象
+	var versionBytes int64
+	if s.versions != nil {
+		if versionBytes, err = s.versions.PurgeVersions(ctx, ids); err != nil {
+			return 0, fmt.Errorf("删除历史版本失败: %w", err)
+		}
+	}
+
+	reclaimed, err := s.trashRepo.Purge(ctx, ids)
+	if err != nil {
+		s.releaseQuota(ctx, entry.UserID, versionBytes)
+		return versionBytes, fmt.Errorf("删除文件记录失败: %w", err)
+	}
+	reclaimed += versionBytes
+	s.releaseQuota(ctx, entry.UserID, reclaimed)
+
+	s.logger.Info("Trash item purged",
+		zap.Uint("user_id", entry.UserID),
+		zap.Uint("trash_id", entry.ID),
+		zap.Int("files", len(ids)),
+		zap.Int64("reclaimed", reclaimed))
+	return reclaimed, nil
+}
+
+// releaseQuota 释放用户的存储用量，失败只记录日志
+func (s *trashService) releaseQuota(ctx context.Context, userID uint, size int64) {
+	if size <= 0 {
+		return
+	}
+	if err := s.accounts.UpdateStorageUsed(ctx, userID, -size); err != nil {
+		// 记录已删除，用量偏差由管理员重新统计修正
+		s.logger.Error("Failed to release storage quota",
+			zap.Uint("user_id", userID),
+			zap.Int64("size", size),
+			zap.Error(err))
+	}
+}
+
+// releaseContent 按保留策略登记文件的存储对象，不需要保留时立即删除
+func (s *trashService) releaseContent(ctx context.Context, userID uint, files []*models.File) error {
+	if s.retainer != nil {
+		retained, err := s.retainer.RetainContent(ctx, userID, files)
+		if err != nil {
+			return err
+		}
+		if retained {
+			return nil
+		}
+	}
+
+	for _, file := range files {
+		if file.IsFolder || file.StoragePath == nil {
+			continue
+		}
+		if err := s.store.Delete(ctx, *file.StoragePath); err != nil {
+			return fmt.Errorf("删除存储对象失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// getOwnedEntry 获取用户自己未恢复的回收站项目
+func (s *trashService) getOwnedEntry(ctx context.Context, userID, itemID uint) (*models.RecycleBin, error) {
+	if userID == 0 || itemID == 0 {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "用户ID和回收站项目ID不能为空")
+	}
+
+	entry, err := s.trashRepo.GetByID(ctx, itemID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "回收站项目不存在")
+		}
+		return nil, fmt.Errorf("获取回收站项目失败: %w", err)
+	}
+	if entry.UserID != userID {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, "无权操作该回收站项目")
+	}
+	if entry.IsRestored {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "回收站项目不存在")
+	}
+	return entry, nil
+}
+
+// restoreTarget 返回恢复的目标文件夹和路径，原文件夹不可用时返回根目录
+func (s *trashService) restoreTarget(ctx context.Context, userID uint, parentID *uint) (*uint, string) {
+	if parentID == nil {
+		return nil, "/"
+	}
+	parent, err := s.fileRepo.GetByID(ctx, *parentID)
+	if err != nil || parent.UserID != userID || !parent.IsFolder || !parent.IsActive() {
+		return nil, "/"
+	}
+	return parentID, parent.GetFullPath()
+}
+
+// availableName 返回目标文件夹下不冲突的名称，冲突时在扩展名前追加序号
+func (s *trashService) availableName(ctx context.Context, userID uint, parentID *uint, name string, isFolder bool) (string, error) {
+	candidate, ok, err := nextAvailableName(name, isFolder, func(candidate string) (bool, error) {
+		return s.trashRepo.NameExists(ctx, userID, parentID, candidate)
+	})
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", pkgErrors.WrapError(pkgErrors.ErrResourceExists, "目标位置同名文件过多，请先重命名后再恢复")
+	}
+	return candidate, nil
+}
+
+// invalidateChecksums 使父链上的文件夹校验和失效，失败只记录日志
+func (s *trashService) invalidateChecksums(ctx context.Context, fileID uint) {
+	if s.checksums == nil {
+		return
+	}
+	if err := s.checksums.InvalidateChecksums(ctx, fileID); err != nil {
+		s.logger.Warn("Failed to invalidate folder checksums",
+			zap.Uint("file_id", fileID),
+			zap.Error(err))
+	}
+}
+
+// selectTrashedFiles 从子树中选出本项目移入回收站的文件
+//
+// 缺少文件ID元数据时恢复子树中全部已删除的文件
+func selectTrashedFiles(entry *models.RecycleBin, subtree []*models.File) []*models.File {
+	ids := trashedFileIDs(entry)
+	files := make([]*models.File, 0, len(subtree))
+	for _, file := range subtree {
+		if !file.DeletedAt.Valid {
+			continue
+		}
+		if ids != nil && !ids[file.ID] && file.ID != entry.FileID {
+			continue
+		}
+		files = append(files, file)
+	}
+	return files
+}
+
+// trashedFileIDs 读取回收站项目元数据中的文件ID，缺少时返回nil
+func trashedFileIDs(entry *models.RecycleBin) map[uint]bool {
+	if entry.Metadata == nil {
+		return nil
+	}
+	raw, ok := (*entry.Metadata)[trashMetaFileIDs].([]interface{})
+	if !ok {
+		return nil
+	}
+
+	ids := make(map[uint]bool, len(raw))
+	for _, value := range raw {
+		// 从JSON读取的数字为float64
+		if id, ok := value.(float64); ok && id > 0 {
+			ids[uint(id)] = true
+		}
+	}
+	return ids
+}
+
+// newTrashItem 将回收站记录转换为返回给用户的项目
+func newTrashItem(entry *models.RecycleBin) *TrashItem {
+	original := &models.File{Name: entry.OriginalName, Path: entry.OriginalPath}
+	return &TrashItem{
+		ID:           entry.ID,
+		FileID:       entry.FileID,
+		Name:         entry.OriginalName,
+		OriginalPath: original.GetFullPath(),
+		IsFolder:     entry.IsFolder,
+		Size:         entry.FileSize,
+		TrashedAt:    entry.TrashedAt,
+		AutoDeleteAt: entry.AutoDeleteAt,
+	}
+}
+
+// sameParent 比较两个可为空的父文件夹ID
+func sameParent(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// nextAvailableName 依次尝试 name、"base (1).ext"、"base (2).ext"...，返回第一个不冲突的名称
+//
+// 文件夹不区分扩展名；超过最大尝试次数时 ok 为false
+func nextAvailableName(name string, isFolder bool, exists func(candidate string) (bool, error)) (candidate string, ok bool, err error) {
+	base, ext := name, ""
+	if !isFolder {
+		ext = path.Ext(name)
+		base = strings.TrimSuffix(name, ext)
+	}
+
+	candidate = name
+	for i := 1; i <= maxNameAttempts; i++ {
+		taken, err := exists(candidate)
+		if err != nil {
+			return "", false, fmt.Errorf("检查同名文件失败: %w", err)
+		}
+		if !taken {
+			return candidate, true, nil
+		}
+		candidate = fmt.Sprintf("%s (%d)%s", base, i, ext)
+	}
+	return "", false, nil
+}
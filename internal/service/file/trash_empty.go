@@ -0,0 +1,243 @@
+package file
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+)
+
+// 清空回收站相关常量
+const (
+	// emptyTrashTokenTTL 清空回收站确认令牌的有效期
+	emptyTrashTokenTTL = 5 * time.Minute
+	// emptyTrashJobRetention 已结束的清空任务在内存中保留的时间
+	emptyTrashJobRetention = 24 * time.Hour
+	// emptyTrashBatchSize 清空时每批读取的回收站项目数
+	emptyTrashBatchSize = 100
+)
+
+// 清空回收站任务状态
+const (
+	EmptyTrashJobRunning   = "running"   // 执行中
+	EmptyTrashJobCompleted = "completed" // 已完成(个别项目失败见failed)
+)
+
+// EmptyTrashConfirmation 清空回收站确认信息
+type EmptyTrashConfirmation struct {
+	Token     string    `json:"confirm_token"` // 确认令牌，清空时提交，只能使用一次
+	Items     int64     `json:"items"`         // 将被彻底删除的项目数
+	Size      int64     `json:"size"`          // 将释放的存储空间
+	Async     bool      `json:"async"`         // 项目较多，清空将在后台执行
+	ExpiresAt time.Time `json:"expires_at"`    // 令牌过期时间
+}
+
+// EmptyTrashJob 清空回收站任务进度
+type EmptyTrashJob struct {
+	ID         string     `json:"id"`                    // 任务ID
+	Status     string     `json:"status"`                // running/completed
+	Async      bool       `json:"async"`                 // 是否在后台执行
+	Total      int64      `json:"total"`                 // 确认时回收站中的项目数
+	Purged     int64      `json:"purged"`                // 已彻底删除的项目数
+	Failed     int64      `json:"failed"`                // 删除失败的项目数，失败的项目仍在回收站中
+	Reclaimed  int64      `json:"reclaimed"`             // 已释放的存储空间
+	Error      string     `json:"error,omitempty"`       // 任务中止的原因
+	StartedAt  time.Time  `json:"started_at"`            // 开始时间
+	FinishedAt *time.Time `json:"finished_at,omitempty"` // 结束时间
+
+	userID uint
+	before time.Time
+}
+
+// emptyTrashToken 已签发的清空回收站确认令牌
+type emptyTrashToken struct {
+	userID    uint
+	items     int64
+	issuedAt  time.Time
+	expiresAt time.Time
+}
+
+// PrepareEmpty 统计回收站中的项目并签发清空确认令牌
+func (s *trashService) PrepareEmpty(ctx context.Context, userID uint) (*EmptyTrashConfirmation, error) {
+	if userID == 0 {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "用户ID不能为空")
+	}
+
+	items, size, err := s.trashRepo.UsageByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("统计回收站失败: %w", err)
+	}
+	token, err := newEmptyTrashID()
+	if err != nil {
+		return nil, fmt.Errorf("生成确认令牌失败: %w", err)
+	}
+
+	now := s.now()
+	record := &emptyTrashToken{userID: userID, items: items, issuedAt: now, expiresAt: now.Add(emptyTrashTokenTTL)}
+	s.mu.Lock()
+	s.pruneEmptyLocked(now)
+	s.emptyTokens[token] = record
+	s.mu.Unlock()
+
+	return &EmptyTrashConfirmation{
+		Token:     token,
+		Items:     items,
+		Size:      size,
+		Async:     items > int64(s.options.EmptyAsyncThreshold),
+		ExpiresAt: record.expiresAt,
+	}, nil
+}
+
+// EmptyTrash 凭确认令牌清空回收站
+//
+// 只删除签发令牌时已在回收站中的项目；项目数超过阈值时在后台执行并立即返回任务进度，
+// 否则执行完成后返回。每个项目彻底删除后立即释放其存储配额
+func (s *trashService) EmptyTrash(ctx context.Context, userID uint, token string) (*EmptyTrashJob, error) {
+	if userID == 0 || token == "" {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "用户ID和确认令牌不能为空")
+	}
+	jobID, err := newEmptyTrashID()
+	if err != nil {
+		return nil, fmt.Errorf("生成任务ID失败: %w", err)
+	}
+
+	now := s.now()
+	s.mu.Lock()
+	s.pruneEmptyLocked(now)
+	record, ok := s.emptyTokens[token]
+	if !ok || record.userID != userID {
+		s.mu.Unlock()
+		return nil, pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "确认令牌无效或已过期，请重新确认")
+	}
+	for _, job := range s.emptyJobs {
+		if job.userID == userID && job.Status == EmptyTrashJobRunning {
+			s.mu.Unlock()
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceExists, "回收站正在清空，请稍后再试")
+		}
+	}
+	delete(s.emptyTokens, token)
+	job := &EmptyTrashJob{
+		ID:        jobID,
+		Status:    EmptyTrashJobRunning,
+		Async:     record.items > int64(s.options.EmptyAsyncThreshold),
+		Total:     record.items,
+		StartedAt: now,
+		userID:    userID,
+		before:    record.issuedAt,
+	}
+	s.emptyJobs[job.ID] = job
+	snapshot := *job
+	s.mu.Unlock()
+
+	s.logger.Info("Emptying trash",
+		zap.String("job_id", job.ID),
+		zap.Uint("user_id", userID),
+		zap.Int64("items", job.Total),
+		zap.Bool("async", job.Async))
+
+	if !job.Async {
+		s.runEmpty(ctx, job)
+		return s.GetEmptyJob(ctx, userID, job.ID)
+	}
+	// 后台任务不随请求结束而取消；实例重启时未删除的项目留在回收站中，可以重新清空
+	runCtx := context.WithoutCancel(ctx)
+	s.emptyAsyncRun(func() { s.runEmpty(runCtx, job) })
+	return &snapshot, nil
+}
+
+// GetEmptyJob 查询用户的清空回收站任务进度
+func (s *trashService) GetEmptyJob(_ context.Context, userID uint, jobID string) (*EmptyTrashJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.emptyJobs[jobID]
+	if !ok || job.userID != userID {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "清空任务不存在或已过期")
+	}
+	snapshot := *job
+	return &snapshot, nil
+}
+
+// runEmpty 按ID顺序分批彻底删除回收站项目，单个项目失败不影响其他项目
+func (s *trashService) runEmpty(ctx context.Context, job *EmptyTrashJob) {
+	var afterID uint
+	for ctx.Err() == nil {
+		entries, err := s.trashRepo.ListByUserBefore(ctx, job.userID, job.before, afterID, emptyTrashBatchSize)
+		if err != nil {
+			s.logger.Error("Failed to list trash items to empty",
+				zap.String("job_id", job.ID),
+				zap.Error(err))
+			s.mu.Lock()
+			job.Error = "读取回收站项目失败"
+			s.mu.Unlock()
+			break
+		}
+
+		for _, entry := range entries {
+			reclaimed, err := s.purge(ctx, entry)
+			s.mu.Lock()
+			job.Reclaimed += reclaimed
+			if err != nil {
+				job.Failed++
+			} else {
+				job.Purged++
+			}
+			s.mu.Unlock()
+			if err != nil {
+				s.logger.Warn("Failed to purge trash item",
+					zap.String("job_id", job.ID),
+					zap.Uint("trash_id", entry.ID),
+					zap.Error(err))
+			}
+			afterID = entry.ID
+		}
+		if len(entries) < emptyTrashBatchSize {
+			break
+		}
+	}
+
+	finishedAt := s.now()
+	s.mu.Lock()
+	if ctx.Err() != nil && job.Error == "" {
+		job.Error = "任务已取消"
+	}
+	job.Status = EmptyTrashJobCompleted
+	job.FinishedAt = &finishedAt
+	snapshot := *job
+	s.mu.Unlock()
+
+	s.logger.Info("Trash emptied",
+		zap.String("job_id", snapshot.ID),
+		zap.Uint("user_id", snapshot.userID),
+		zap.Int64("purged", snapshot.Purged),
+		zap.Int64("failed", snapshot.Failed),
+		zap.Int64("reclaimed", snapshot.Reclaimed))
+}
+
+// pruneEmptyLocked 清理过期的确认令牌和结束超过保留时间的任务，调用方需持有锁
+func (s *trashService) pruneEmptyLocked(now time.Time) {
+	for token, record := range s.emptyTokens {
+		if !now.Before(record.expiresAt) {
+			delete(s.emptyTokens, token)
+		}
+	}
+	for id, job := range s.emptyJobs {
+		if job.FinishedAt != nil && now.Sub(*job.FinishedAt) > emptyTrashJobRetention {
+			delete(s.emptyJobs, id)
+		}
+	}
+}
+
+// newEmptyTrashID 生成随机的确认令牌或任务ID
+func newEmptyTrashID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
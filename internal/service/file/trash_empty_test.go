@@ -0,0 +1,91 @@
+package file
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+)
+
+func TestTrashService_EmptyTrash(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("confirmed empty purges items trashed before confirmation", func(t *testing.T) {
+		f := newTrashFixture()
+		_, err := f.service.MoveToTrash(ctx, 7, 3)
+		require.NoError(t, err)
+		f.accounts.On("UpdateStorageUsed", mock.Anything, uint(7), int64(-50)).Return(nil).Once()
+
+		confirmation, err := f.service.PrepareEmpty(ctx, 7)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), confirmation.Items)
+		assert.Equal(t, int64(50), confirmation.Size)
+		assert.False(t, confirmation.Async)
+
+		// 确认之后移入的项目不被清空
+		f.service.now = func() time.Time { return trashTestNow.Add(time.Minute) }
+		later, err := f.service.MoveToTrash(ctx, 7, 2)
+		require.NoError(t, err)
+
+		// 令牌属于签发的用户
+		_, err = f.service.EmptyTrash(ctx, 8, confirmation.Token)
+		assert.True(t, pkgErrors.IsValidationError(err))
+
+		job, err := f.service.EmptyTrash(ctx, 7, confirmation.Token)
+		require.NoError(t, err)
+		assert.Equal(t, EmptyTrashJobCompleted, job.Status)
+		assert.Equal(t, int64(1), job.Purged)
+		assert.Equal(t, int64(50), job.Reclaimed)
+		require.NotNil(t, job.FinishedAt)
+		assert.Contains(t, f.trash.entries, later.ID)
+		assert.Len(t, f.trash.entries, 1)
+		f.accounts.AssertExpectations(t)
+
+		// 令牌只能使用一次
+		_, err = f.service.EmptyTrash(ctx, 7, confirmation.Token)
+		assert.True(t, pkgErrors.IsValidationError(err))
+	})
+
+	t.Run("large trash emptied in background", func(t *testing.T) {
+		f := newTrashFixture()
+		f.service.options.EmptyAsyncThreshold = 1
+		var background func()
+		f.service.emptyAsyncRun = func(run func()) { background = run }
+		for _, id := range []uint{2, 4} {
+			_, err := f.service.MoveToTrash(ctx, 7, id)
+			require.NoError(t, err)
+		}
+		f.accounts.On("UpdateStorageUsed", mock.Anything, uint(7), mock.AnythingOfType("int64")).Return(nil)
+
+		confirmation, err := f.service.PrepareEmpty(ctx, 7)
+		require.NoError(t, err)
+		assert.True(t, confirmation.Async)
+		job, err := f.service.EmptyTrash(ctx, 7, confirmation.Token)
+		require.NoError(t, err)
+		assert.Equal(t, EmptyTrashJobRunning, job.Status)
+		assert.True(t, job.Async)
+
+		// 同一用户同时只能有一个清空任务
+		again, err := f.service.PrepareEmpty(ctx, 7)
+		require.NoError(t, err)
+		_, err = f.service.EmptyTrash(ctx, 7, again.Token)
+		assert.ErrorIs(t, err, pkgErrors.ErrResourceExists)
+
+		require.NotNil(t, background)
+		background()
+		job, err = f.service.GetEmptyJob(ctx, 7, job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, EmptyTrashJobCompleted, job.Status)
+		assert.Equal(t, int64(2), job.Purged)
+		assert.Equal(t, int64(150), job.Reclaimed)
+		assert.Empty(t, f.trash.entries)
+
+		_, err = f.service.GetEmptyJob(ctx, 8, job.ID)
+		assert.True(t, pkgErrors.IsNotFoundError(err))
+	})
+}
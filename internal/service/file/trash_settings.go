@@ -0,0 +1,132 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/repository/models"
+)
+
+// trashDay 回收站保留时长的设置单位
+const trashDay = 24 * time.Hour
+
+// TrashPreferenceStore 用户偏好读写，由 userrepo.UserRepository 实现
+type TrashPreferenceStore interface {
+	GetUserPreferences(ctx context.Context, userID uint, category string) ([]*models.UserPreference, error)
+	SetUserPreference(ctx context.Context, userID uint, category, key, value string) error
+	DeleteUserPreference(ctx context.Context, userID uint, category, key string) error
+}
+
+// TrashSettings 用户的回收站设置
+type TrashSettings struct {
+	RetentionDays int  `json:"retention_days"` // 当前生效的保留天数
+	Custom        bool `json:"custom"`         // 是否为用户自定义的保留天数
+	DefaultDays   int  `json:"default_days"`   // 系统默认保留天数
+	MinDays       int  `json:"min_days"`       // 可设置的最短保留天数
+	MaxDays       int  `json:"max_days"`       // 可设置的最长保留天数
+}
+
+// GetSettings 查询用户的回收站设置
+func (s *trashService) GetSettings(ctx context.Context, userID uint) (*TrashSettings, error) {
+	if userID == 0 {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "用户ID不能为空")
+	}
+
+	custom, err := s.customRetentionDays(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := &TrashSettings{
+		DefaultDays: int(s.options.Retention / trashDay),
+		MinDays:     int((s.options.MinRetention + trashDay - 1) / trashDay),
+		MaxDays:     int(s.options.MaxRetention / trashDay),
+	}
+	settings.RetentionDays = settings.DefaultDays
+	if custom > 0 {
+		// 管理员调整范围后，超出范围的自定义值按边界生效
+		settings.RetentionDays = min(max(custom, settings.MinDays), settings.MaxDays)
+		settings.Custom = true
+	}
+	return settings, nil
+}
+
+// UpdateSettings 设置用户的回收站保留天数，retentionDays为0时恢复系统默认
+//
+// 新的保留天数对之后移入回收站的项目生效，已在回收站中的项目保持原来的自动清理时间
+func (s *trashService) UpdateSettings(ctx context.Context, userID uint, retentionDays int) (*TrashSettings, error) {
+	if userID == 0 {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "用户ID不能为空")
+	}
+	if s.prefs == nil {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "不支持自定义回收站保留时长")
+	}
+
+	if retentionDays == 0 {
+		if err := s.prefs.DeleteUserPreference(ctx, userID, models.PreferenceCategoryFile, models.PreferenceKeyTrashRetention); err != nil {
+			return nil, fmt.Errorf("恢复回收站默认设置失败: %w", err)
+		}
+	} else {
+		current, err := s.GetSettings(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if retentionDays < current.MinDays || retentionDays > current.MaxDays {
+			return nil, pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "回收站保留天数必须在%d到%d之间", current.MinDays, current.MaxDays)
+		}
+		err = s.prefs.SetUserPreference(ctx, userID, models.PreferenceCategoryFile, models.PreferenceKeyTrashRetention, strconv.Itoa(retentionDays))
+		if err != nil {
+			return nil, fmt.Errorf("保存回收站设置失败: %w", err)
+		}
+	}
+
+	s.logger.Info("Trash retention updated",
+		zap.Uint("user_id", userID),
+		zap.Int("retention_days", retentionDays))
+	return s.GetSettings(ctx, userID)
+}
+
+// retention 返回用户移入回收站的项目的保留时长，读取设置失败时使用系统默认
+func (s *trashService) retention(ctx context.Context, userID uint) time.Duration {
+	if s.prefs == nil {
+		return s.options.Retention
+	}
+	settings, err := s.GetSettings(ctx, userID)
+	if err != nil {
+		s.logger.Warn("Failed to load trash settings, using default retention",
+			zap.Uint("user_id", userID),
+			zap.Error(err))
+		return s.options.Retention
+	}
+	if !settings.Custom {
+		return s.options.Retention
+	}
+	return time.Duration(settings.RetentionDays) * trashDay
+}
+
+// customRetentionDays 读取用户自定义的保留天数，未设置或无法解析时返回0
+func (s *trashService) customRetentionDays(ctx context.Context, userID uint) (int, error) {
+	if s.prefs == nil {
+		return 0, nil
+	}
+	prefs, err := s.prefs.GetUserPreferences(ctx, userID, models.PreferenceCategoryFile)
+	if err != nil {
+		return 0, fmt.Errorf("获取回收站设置失败: %w", err)
+	}
+	for _, pref := range prefs {
+		if pref.Key != models.PreferenceKeyTrashRetention {
+			continue
+		}
+		days, err := strconv.Atoi(pref.GetStringValue())
+		if err != nil || days <= 0 {
+			return 0, nil
+		}
+		return days, nil
+	}
+	return 0, nil
+}
@@ -0,0 +1,81 @@
+package file
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/repository/models"
+)
+
+// memoryPreferences 内存用户偏好
+type memoryPreferences struct {
+	values map[uint]map[string]string
+}
+
+func (m *memoryPreferences) GetUserPreferences(_ context.Context, userID uint, category string) ([]*models.UserPreference, error) {
+	var prefs []*models.UserPreference
+	for key, value := range m.values[userID] {
+		value := value
+		prefs = append(prefs, &models.UserPreference{UserID: userID, Category: category, Key: key, Value: &value})
+	}
+	return prefs, nil
+}
+
+func (m *memoryPreferences) SetUserPreference(_ context.Context, userID uint, _, key, value string) error {
+	if m.values[userID] == nil {
+		m.values[userID] = make(map[string]string)
+	}
+	m.values[userID][key] = value
+	return nil
+}
+
+func (m *memoryPreferences) DeleteUserPreference(_ context.Context, userID uint, _, key string) error {
+	delete(m.values[userID], key)
+	return nil
+}
+
+func TestTrashService_Settings(t *testing.T) {
+	ctx := context.Background()
+	f := newTrashFixture()
+	prefs := &memoryPreferences{values: make(map[uint]map[string]string)}
+	f.service.prefs = prefs
+	f.service.options = TrashOptions{Retention: 30 * trashDay, MinRetention: trashDay, MaxRetention: 90 * trashDay}
+
+	settings, err := f.service.GetSettings(ctx, 7)
+	require.NoError(t, err)
+	assert.Equal(t, &TrashSettings{RetentionDays: 30, DefaultDays: 30, MinDays: 1, MaxDays: 90}, settings)
+
+	// 超出管理员设置的范围
+	_, err = f.service.UpdateSettings(ctx, 7, 91)
+	assert.True(t, pkgErrors.IsValidationError(err))
+
+	settings, err = f.service.UpdateSettings(ctx, 7, 7)
+	require.NoError(t, err)
+	assert.Equal(t, 7, settings.RetentionDays)
+	assert.True(t, settings.Custom)
+
+	// 新移入的项目按自定义保留天数自动清理
+	item, err := f.service.MoveToTrash(ctx, 7, 2)
+	require.NoError(t, err)
+	assert.Equal(t, trashTestNow.Add(7*24*time.Hour), item.AutoDeleteAt)
+
+	// 管理员缩小范围后按边界生效
+	f.service.options.MaxRetention = 30 * trashDay
+	prefs.values[7][models.PreferenceKeyTrashRetention] = "60"
+	assert.Equal(t, 30*trashDay, f.service.retention(ctx, 7))
+
+	settings, err = f.service.UpdateSettings(ctx, 7, 0)
+	require.NoError(t, err)
+	assert.False(t, settings.Custom)
+	assert.Equal(t, 30, settings.RetentionDays)
+
+	// 未配置偏好存储时不能自定义
+	f.service.prefs = nil
+	_, err = f.service.UpdateSettings(ctx, 7, 7)
+	assert.True(t, pkgErrors.IsPermissionError(err))
+}
@@ -0,0 +1,365 @@
+package file
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/repository/models"
+)
+
+// memoryTrash 内存回收站，同时提供过滤已删除文件的文件仓储
+type memoryTrash struct {
+	memoryFileRepository
+	entries map[uint]*models.RecycleBin
+	nextID  uint
+}
+
+func newMemoryTrash(files ...*models.File) *memoryTrash {
+	trash := &memoryTrash{
+		memoryFileRepository: memoryFileRepository{files: make(map[uint]*models.File)},
+		entries:              make(map[uint]*models.RecycleBin),
+	}
+	for _, f := range files {
+		trash.files[f.ID] = f
+	}
+	return trash
+}
+
+func (m *memoryTrash) GetByID(_ context.Context, id uint) (*models.File, error) {
+	if f, ok := m.files[id]; ok && !f.DeletedAt.Valid {
+		return f, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *memoryTrash) MoveToTrash(_ context.Context, entry *models.RecycleBin, fileIDs []uint) error {
+	m.nextID++
+	entry.ID = m.nextID
+	m.entries[entry.ID] = entry
+	for _, id := range fileIDs {
+		f := m.files[id]
+		if f.Status == "active" {
+			f.Status = "deleted"
+		}
+		f.DeletedAt = gorm.DeletedAt{Time: entry.TrashedAt, Valid: true}
+	}
+	return nil
+}
+
+func (m *memoryTrash) ListByUser(_ context.Context, userID uint, limit, offset int) ([]*models.RecycleBin, int64, error) {
+	var entries []*models.RecycleBin
+	for _, entry := range m.entries {
+		if entry.UserID == userID && !entry.IsRestored {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, int64(len(entries)), nil
+}
+
+func (m *memoryTrash) ListByUserBefore(_ context.Context, userID uint, before time.Time, afterID uint, limit int) ([]*models.RecycleBin, error) {
+	var entries []*models.RecycleBin
+	for _, entry := range m.entries {
+		if entry.UserID == userID && !entry.IsRestored && !entry.TrashedAt.After(before) && entry.ID > afterID {
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+	return entries[:min(limit, len(entries))], nil
+}
+
+func (m *memoryTrash) UsageByUser(_ context.Context, userID uint) (int64, int64, error) {
+	var items, size int64
+	for _, entry := range m.entries {
+		if entry.UserID == userID && !entry.IsRestored {
+			items++
+			size += entry.FileSize
+		}
+	}
+	return items, size, nil
+}
+
+func (m *memoryTrash) ListExpired(_ context.Context, now time.Time, limit int) ([]*models.RecycleBin, error) {
+	var entries []*models.RecycleBin
+	for _, entry := range m.entries {
+		if !entry.IsRestored && entry.AutoDeleteAt.Before(now) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+func (m *memoryTrash) GetFile(_ context.Context, id uint) (*models.File, error) {
+	if f, ok := m.files[id]; ok {
+		return f, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *memoryTrash) ListSubtree(_ context.Context, root *models.File) ([]*models.File, error) {
+	if !root.IsFolder {
+		return []*models.File{root}, nil
+	}
+	full := root.GetFullPath()
+	files := []*models.File{root}
+	for _, f := range m.files {
+		if f.ID != root.ID && f.UserID == root.UserID && (f.Path == full || strings.HasPrefix(f.Path, full+"/")) {
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+func (m *memoryTrash) NameExists(_ context.Context, userID uint, parentID *uint, name string) (bool, error) {
+	for _, f := range m.files {
+		if f.UserID == userID && !f.DeletedAt.Valid && f.Name == name && sameParent(f.ParentID, parentID) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *memoryTrash) Restore(_ context.Context, entry *models.RecycleBin, files []*models.File, restoredBy uint, restoredAt time.Time) error {
+	for _, f := range files {
+		f.DeletedAt = gorm.DeletedAt{}
+		if f.Status == "deleted" {
+			f.Status = "active"
+		}
+	}
+	entry.IsRestored = true
+	return nil
+}
+
+func (m *memoryTrash) Purge(_ context.Context, fileIDs []uint) (int64, error) {
+	var reclaimed int64
+	for _, id := range fileIDs {
+		for entryID, entry := range m.entries {
+			if entry.FileID == id {
+				if !entry.IsRestored {
+					reclaimed += entry.FileSize
+				}
+				delete(m.entries, entryID)
+			}
+		}
+		delete(m.files, id)
+	}
+	return reclaimed, nil
+}
+
+func (m *memoryTrash) entryByID(id uint) (*models.RecycleBin, error) {
+	if entry, ok := m.entries[id]; ok {
+		return entry, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// memoryTrashEntries 将回收站项目查询适配为仓储接口的 GetByID
+type memoryTrashEntries struct {
+	*memoryTrash
+}
+
+func (m memoryTrashEntries) GetByID(_ context.Context, id uint) (*models.RecycleBin, error) {
+	return m.entryByID(id)
+}
+
+// recordingDeleter 记录删除的存储对象
+type recordingDeleter struct {
+	deleted []string
+}
+
+func (d *recordingDeleter) Delete(_ context.Context, path string) error {
+	d.deleted = append(d.deleted, path)
+	return nil
+}
+
+var trashTestNow = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+type trashFixture struct {
+	service  *trashService
+	trash    *memoryTrash
+	accounts *MockStorageAccountStore
+	store    *recordingDeleter
+}
+
+// newTrashFixture 构建目录树：/docs(1) 下有 /docs/a.txt(2) 和 /docs/sub(3)，/docs/sub 下有 b.txt(4)
+func newTrashFixture() *trashFixture {
+	folder := newTestFile(1, 7, nil, "docs", true)
+	folder.Path = "/"
+	a := newTestFile(2, 7, uintPtr(1), "a.txt", false)
+	a.Path, a.Size, a.StoragePath = "/docs", 100, strPtr("files/a")
+	sub := newTestFile(3, 7, uintPtr(1), "sub", true)
+	sub.Path = "/docs"
+	b := newTestFile(4, 7, uintPtr(3), "b.txt", false)
+	b.Path, b.Size, b.StoragePath = "/docs/sub", 50, strPtr("files/b")
+	for _, f := range []*models.File{folder, a, sub, b} {
+		f.Status = "active"
+	}
+
+	trash := newMemoryTrash(folder, a, sub, b)
+	accounts := new(MockStorageAccountStore)
+	store := &recordingDeleter{}
+	service := NewTrashService(trash, memoryTrashEntries{trash}, accounts, store, nil, nil, nil, nil,
+		TrashOptions{Retention: 24 * time.Hour}, nil).(*trashService)
+	service.now = func() time.Time { return trashTestNow }
+	return &trashFixture{service: service, trash: trash, accounts: accounts, store: store}
+}
+
+func TestTrashService_MoveToTrash(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("folder with descendants", func(t *testing.T) {
+		f := newTrashFixture()
+		item, err := f.service.MoveToTrash(ctx, 7, 1)
+		require.NoError(t, err)
+		assert.Equal(t, "/docs", item.OriginalPath)
+		assert.Equal(t, int64(150), item.Size)
+		assert.Equal(t, trashTestNow.Add(24*time.Hour), item.AutoDeleteAt)
+		for id := uint(1); id <= 4; id++ {
+			assert.True(t, f.trash.files[id].DeletedAt.Valid, "文件%d应被软删除", id)
+		}
+
+		items, total, err := f.service.ListTrash(ctx, 7, 1, 20)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), total)
+		assert.Equal(t, item.ID, items[0].ID)
+	})
+
+	t.Run("other user's file", func(t *testing.T) {
+		f := newTrashFixture()
+		_, err := f.service.MoveToTrash(ctx, 8, 2)
+		assert.True(t, pkgErrors.IsPermissionError(err))
+	})
+}
+
+func TestTrashService_Restore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("restores subtree to original location", func(t *testing.T) {
+		f := newTrashFixture()
+		item, err := f.service.MoveToTrash(ctx, 7, 1)
+		require.NoError(t, err)
+
+		restored, err := f.service.Restore(ctx, 7, item.ID)
+		require.NoError(t, err)
+		assert.Equal(t, &RestoredFile{FileID: 1, Name: "docs", Path: "/docs"}, restored)
+		assert.Equal(t, "active", f.trash.files[4].Status)
+		assert.False(t, f.trash.files[4].DeletedAt.Valid)
+
+		_, err = f.service.Restore(ctx, 7, item.ID)
+		assert.True(t, pkgErrors.IsNotFoundError(err), "已恢复的项目不能再次恢复")
+	})
+
+	t.Run("parent gone and name taken", func(t *testing.T) {
+		f := newTrashFixture()
+		subItem, err := f.service.MoveToTrash(ctx, 7, 3)
+		require.NoError(t, err)
+		_, err = f.service.MoveToTrash(ctx, 7, 1)
+		require.NoError(t, err)
+		taken := newTestFile(9, 7, nil, "sub", true)
+		taken.Path, taken.Status = "/", "active"
+		f.trash.files[9] = taken
+
+		restored, err := f.service.Restore(ctx, 7, subItem.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "sub (1)", restored.Name)
+		assert.Equal(t, "/sub (1)", restored.Path)
+		assert.True(t, restored.Relocated)
+		assert.True(t, restored.Renamed)
+		assert.Equal(t, "/sub (1)", f.trash.files[4].Path, "子项路径随之更新")
+		assert.True(t, f.trash.files[2].DeletedAt.Valid, "父文件夹的其他内容仍在回收站中")
+	})
+
+	t.Run("file name conflict keeps extension", func(t *testing.T) {
+		f := newTrashFixture()
+		item, err := f.service.MoveToTrash(ctx, 7, 2)
+		require.NoError(t, err)
+		other := newTestFile(9, 7, uintPtr(1), "a.txt", false)
+		other.Path, other.Status = "/docs", "active"
+		f.trash.files[9] = other
+
+		restored, err := f.service.Restore(ctx, 7, item.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "/docs/a (1).txt", restored.Path)
+		assert.False(t, restored.Relocated)
+	})
+
+	t.Run("expired item", func(t *testing.T) {
+		f := newTrashFixture()
+		item, err := f.service.MoveToTrash(ctx, 7, 2)
+		require.NoError(t, err)
+		f.service.now = func() time.Time { return trashTestNow.Add(25 * time.Hour) }
+
+		_, err = f.service.Restore(ctx, 7, item.ID)
+		assert.True(t, pkgErrors.IsPermissionError(err))
+	})
+}
+
+// stubVersionPurger 记录需要清理历史版本的文件
+type stubVersionPurger struct {
+	fileIDs   []uint
+	reclaimed int64
+}
+
+func (s *stubVersionPurger) PurgeVersions(_ context.Context, fileIDs []uint) (int64, error) {
+	s.fileIDs = append(s.fileIDs, fileIDs...)
+	return s.reclaimed, nil
+}
+
+func TestTrashService_Purge(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("delete permanently releases quota", func(t *testing.T) {
+		f := newTrashFixture()
+		item, err := f.service.MoveToTrash(ctx, 7, 1)
+		require.NoError(t, err)
+		f.accounts.On("UpdateStorageUsed", mock.Anything, uint(7), int64(-150)).Return(nil).Once()
+
+		require.NoError(t, f.service.DeletePermanently(ctx, 7, item.ID))
+		assert.ElementsMatch(t, []string{"files/a", "files/b"}, f.store.deleted)
+		assert.Empty(t, f.trash.files)
+		assert.Empty(t, f.trash.entries)
+		f.accounts.AssertExpectations(t)
+	})
+
+	t.Run("history versions purged with files", func(t *testing.T) {
+		f := newTrashFixture()
+		versions := &stubVersionPurger{reclaimed: 30}
+		f.service.versions = versions
+		item, err := f.service.MoveToTrash(ctx, 7, 1)
+		require.NoError(t, err)
+		f.accounts.On("UpdateStorageUsed", mock.Anything, uint(7), int64(-180)).Return(nil).Once()
+
+		require.NoError(t, f.service.DeletePermanently(ctx, 7, item.ID))
+		assert.ElementsMatch(t, []uint{1, 2, 3, 4}, versions.fileIDs)
+		f.accounts.AssertExpectations(t)
+	})
+
+	t.Run("expired items purged with nested entries", func(t *testing.T) {
+		f := newTrashFixture()
+		_, err := f.service.MoveToTrash(ctx, 7, 3)
+		require.NoError(t, err)
+		_, err = f.service.MoveToTrash(ctx, 7, 1)
+		require.NoError(t, err)
+		f.service.now = func() time.Time { return trashTestNow.Add(25 * time.Hour) }
+		f.accounts.On("UpdateStorageUsed", mock.Anything, uint(7), mock.AnythingOfType("int64")).Return(nil)
+
+		purged, err := f.service.PurgeExpired(ctx, 10)
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, purged, int64(1))
+		assert.Empty(t, f.trash.files)
+
+		var released int64
+		for _, call := range f.accounts.Calls {
+			released += call.Arguments.Get(2).(int64)
+		}
+		assert.Equal(t, int64(-150), released, "每个文件的用量只释放一次")
+	})
+}
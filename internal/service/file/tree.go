@@ -0,0 +1,726 @@
+package file
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/cache"
+	basemodels "cloudpan/internal/pkg/database/models"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/storage"
+	"cloudpan/internal/pkg/utils"
+	filerepo "cloudpan/internal/repository/file"
+	"cloudpan/internal/repository/models"
+)
+
+// DefaultMaxCopyFiles 单次复制的默认最大文件数(含文件夹)
+const DefaultMaxCopyFiles = 10000
+
+// DefaultMaxCollatedEntries 按自然排序或语言规则排序时在内存中排序的默认最大子项数
+const DefaultMaxCollatedEntries = 5000
+
+// TreeService 文件树操作服务接口
+//
+// 提供文件和文件夹的位置变更操作，包括：
+// 1. 重命名：同一文件夹下不允许重名，文件夹的全部子项路径随之更新
+// 2. 移动：在一个事务中更新根项目的位置和全部子项的路径，禁止移动到自身或子文件夹中
+// 3. 复制：复制存储对象并创建新的文件记录，目标位置重名时自动追加序号，占用存储配额
+// 4. 浏览和新建：分页列出文件夹内容(名称支持自然排序和按语言排序)，在文件夹下新建子文件夹
+//
+// 每次操作后沿原位置和新位置的父链使文件夹校验和失效，并清除受影响文件的信息、预览和下载缓存；
+// 移动、复制和新建文件夹前按 FolderLimiter 检查目标文件夹的层级和子项数
+//
+// 使用示例：
+//
+//	service := NewTreeService(fileRepo, folderRepo, userRepo, store, fileService, cacheManager, folderLimiter, TreeOptions{}, logger)
+//	folder, err := service.Move(ctx, userID, folderID, &targetID)
+//	copied, err := service.Copy(ctx, userID, fileID, nil)
+//	files, total, err := service.List(ctx, userID, &folderID, ListOptions{Page: 1, PageSize: 50, Collation: utils.CollationNatural})
+type TreeService interface {
+	Rename(ctx context.Context, userID, fileID uint, name string) (*models.File, error)
+	Move(ctx context.Context, userID, fileID uint, targetParentID *uint) (*models.File, error)
+	Copy(ctx context.Context, userID, fileID uint, targetParentID *uint) (*models.File, error)
+
+	List(ctx context.Context, userID uint, parentID *uint, options ListOptions) ([]*models.File, int64, error)
+	CreateFolder(ctx context.Context, userID uint, parentID *uint, name string) (*models.File, error)
+}
+
+// ObjectCopier 存储对象读写，由 storage.Storage 实现
+type ObjectCopier interface {
+	Open(ctx context.Context, path string) (io.ReadCloser, error)
+	Put(ctx context.Context, path string, reader io.Reader, size int64) error
+	Delete(ctx context.Context, path string) error
+}
+
+// CacheDeleter 缓存删除，由 cache.CacheManager 实现
+type CacheDeleter interface {
+	Delete(keys ...string) error
+}
+
+// TreeOptions 文件树操作选项
+type TreeOptions struct {
+	KeyPrefix          string // 复制出的存储对象路径前缀
+	MaxCopyFiles       int    // 单次复制的最大文件数(含文件夹)
+	MaxCollatedEntries int    // 按自然排序或语言规则排序的最大子项数，超过时按数据库索引的字节顺序排序
+}
+
+// ListOptions 文件夹浏览选项
+type ListOptions struct {
+	Page      int    // 页码，从1开始
+	PageSize  int    // 每页数量
+	SortBy    string // 排序列(filerepo.ContentsOrderName等)，为空按名称排序
+	Desc      bool   // 是否降序
+	Collation string // 名称排序规则(utils.CollationBinary等)，只在按名称排序时生效
+	Locale    string // utils.CollationLocale 使用的语言标签，如zh-CN
+
+	ScanStatus    string // 按病毒扫描状态筛选，为空不筛选
+	PreviewStatus string // 按预览生成状态筛选，为空不筛选
+
+	IncludeUploading bool // 是否包含进行中的分片上传占位文件，按处理状态筛选时不包含
+
+	Tags        []string // 按标签筛选，为空不筛选
+	MatchAnyTag bool     // 带有任一标签即可，默认要求带有全部标签
+}
+
+// filter 转换为仓储层的筛选条件
+func (o ListOptions) filter() filerepo.ContentsFilter {
+	return filerepo.ContentsFilter{
+		ScanStatus:       o.ScanStatus,
+		PreviewStatus:    o.PreviewStatus,
+		IncludeUploading: o.IncludeUploading && o.ScanStatus == "" && o.PreviewStatus == "",
+		Tags:             o.Tags,
+		MatchAnyTag:      o.MatchAnyTag,
+	}
+}
+
+// treeService 文件树操作服务实现
+type treeService struct {
+	fileRepo   filerepo.FileRepository
+	folderRepo filerepo.FolderRepository
+	accounts   StorageAccountStore
+	store      ObjectCopier
+	checksums  ChecksumInvalidator
+	cache      CacheDeleter
+	folders    FolderLimiter
+	keys       *cache.KeyBuilder
+	options    TreeOptions
+	logger     *zap.Logger
+	now        func() time.Time
+}
+
+// NewTreeService 创建文件树操作服务实例
+//
+// checksums 和 cacheDeleter 可以为nil(如Redis未初始化)，此时跳过对应的失效处理；folders 为nil时不限制文件夹层级和子项数
+func NewTreeService(fileRepo filerepo.FileRepository, folderRepo filerepo.FolderRepository, accounts StorageAccountStore,
+	store ObjectCopier, checksums ChecksumInvalidator, cacheDeleter CacheDeleter, folders FolderLimiter, options TreeOptions, logger *zap.Logger) TreeService {
+	options.KeyPrefix = strings.Trim(options.KeyPrefix, "/")
+	if options.KeyPrefix == "" {
+		options.KeyPrefix = "files"
+	}
+	if options.MaxCopyFiles <= 0 {
+		options.MaxCopyFiles = DefaultMaxCopyFiles
+	}
+	if options.MaxCollatedEntries <= 0 {
+		options.MaxCollatedEntries = DefaultMaxCollatedEntries
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &treeService{
+		fileRepo:   fileRepo,
+		folderRepo: folderRepo,
+		accounts:   accounts,
+		store:      store,
+		checksums:  checksums,
+		cache:      cacheDeleter,
+		folders:    folders,
+		keys:       cache.NewKeyBuilder(),
+		options:    options,
+		logger:     logger,
+		now:        time.Now,
+	}
+}
+
+// Rename 重命名文件或文件夹
+func (s *treeService) Rename(ctx context.Context, userID, fileID uint, name string) (*models.File, error) {
+	root, err := s.getOwnedActive(ctx, userID, fileID)
+	if err != nil {
+		return nil, err
+	}
+	name, err = utils.NormalizeFileName(name)
+	if err != nil {
+		return nil, err
+	}
+	if name == root.Name {
+		return root, nil
+	}
+	if err := s.ensureNameFree(ctx, userID, root.ParentID, name, root.ID); err != nil {
+		return nil, err
+	}
+
+	subtree, err := s.folderRepo.ListSubtree(ctx, root)
+	if err != nil {
+		return nil, fmt.Errorf("获取文件夹内容失败: %w", err)
+	}
+	if err := s.relocate(ctx, root, subtree, root.ParentID, root.Path, name); err != nil {
+		return nil, err
+	}
+	s.logger.Info("File renamed",
+		zap.Uint("user_id", userID),
+		zap.Uint("file_id", root.ID),
+		zap.String("path", root.GetFullPath()))
+	return root, nil
+}
+
+// Move 将文件或文件夹移动到目标文件夹，targetParentID为nil表示根目录
+func (s *treeService) Move(ctx context.Context, userID, fileID uint, targetParentID *uint) (*models.File, error) {
+	root, err := s.getOwnedActive(ctx, userID, fileID)
+	if err != nil {
+		return nil, err
+	}
+	targetPath, err := s.resolveTarget(ctx, userID, root, targetParentID)
+	if err != nil {
+		return nil, err
+	}
+	if sameParent(root.ParentID, targetParentID) {
+		return root, nil
+	}
+	if err := s.ensureNameFree(ctx, userID, targetParentID, root.Name, root.ID); err != nil {
+		return nil, err
+	}
+
+	subtree, err := s.folderRepo.ListSubtree(ctx, root)
+	if err != nil {
+		return nil, fmt.Errorf("获取文件夹内容失败: %w", err)
+	}
+	if err := checkFolderLimits(ctx, s.folders, userID, targetParentID, targetPath, subtreeHeight(root, subtree)); err != nil {
+		return nil, err
+	}
+
+	oldPath := root.GetFullPath()
+	if err := s.relocate(ctx, root, subtree, targetParentID, targetPath, root.Name); err != nil {
+		return nil, err
+	}
+	s.logger.Info("File moved",
+		zap.Uint("user_id", userID),
+		zap.Uint("file_id", root.ID),
+		zap.String("from", oldPath),
+		zap.String("to", root.GetFullPath()))
+	return root, nil
+}
+
+// Copy 将文件或文件夹复制到目标文件夹，targetParentID为nil表示根目录
+//
+// 非可用状态(如上传中)的文件不复制；存储对象先复制，记录创建失败时删除已复制的对象
+func (s *treeService) Copy(ctx context.Context, userID, fileID uint, targetParentID *uint) (*models.File, error) {
+	root, err := s.getOwnedActive(ctx, userID, fileID)
+	if err != nil {
+		return nil, err
+	}
+	targetPath, err := s.resolveTarget(ctx, userID, root, targetParentID)
+	if err != nil {
+		return nil, err
+	}
+
+	subtree, err := s.folderRepo.ListSubtree(ctx, root)
+	if err != nil {
+		return nil, fmt.Errorf("获取文件夹内容失败: %w", err)
+	}
+	sources, parents := selectCopySources(root, subtree)
+	if len(sources) > s.options.MaxCopyFiles {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed,
+			fmt.Sprintf("单次最多复制 %d 个文件", s.options.MaxCopyFiles))
+	}
+	if err := checkFolderLimits(ctx, s.folders, userID, targetParentID, targetPath, subtreeHeight(root, sources)); err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	var totalSize int64
+	for _, source := range sources {
+		if source.IsFolder {
+			continue
+		}
+		if !source.IsReadable(now) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "包含已归档的文件，请先恢复后再复制")
+		}
+		totalSize += source.Size
+	}
+	if err := s.checkQuota(ctx, userID, totalSize); err != nil {
+		return nil, err
+	}
+
+	name, ok, err := nextAvailableName(root.Name, root.IsFolder, func(candidate string) (bool, error) {
+		return s.folderRepo.NameExists(ctx, userID, targetParentID, candidate, 0)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrResourceExists, "目标文件夹中同名文件过多，请先重命名后再复制")
+	}
+
+	// 根项目使用新名称和位置，子项路径按新的完整路径替换前缀
+	copies := make([]*models.File, len(sources))
+	oldPath := root.GetFullPath()
+	for i, source := range sources {
+		copies[i] = newFileCopy(source)
+	}
+	copies[0].Name, copies[0].ParentID, copies[0].Path = name, targetParentID, targetPath
+	newPath := copies[0].GetFullPath()
+	for _, c := range copies[1:] {
+		c.Path = newPath + strings.TrimPrefix(c.Path, oldPath)
+	}
+
+	copied, err := s.copyObjects(ctx, userID, sources, copies)
+	if err != nil {
+		s.deleteObjects(ctx, copied)
+		return nil, err
+	}
+	if err := s.folderRepo.CreateTree(ctx, copies, parents); err != nil {
+		s.deleteObjects(ctx, copied)
+		return nil, fmt.Errorf("创建文件记录失败: %w", err)
+	}
+
+	if totalSize > 0 {
+		if err := s.accounts.UpdateStorageUsed(ctx, userID, totalSize); err != nil {
+			s.logger.Error("Failed to update storage usage after copy",
+				zap.Uint("user_id", userID),
+				zap.Int64("size", totalSize),
+				zap.Error(err))
+		}
+	}
+	s.invalidateChecksums(ctx, copies[0].ID)
+
+	s.logger.Info("File copied",
+		zap.Uint("user_id", userID),
+		zap.Uint("file_id", root.ID),
+		zap.Uint("copy_id", copies[0].ID),
+		zap.Int("files", len(copies)),
+		zap.Int64("size", totalSize))
+	return copies[0], nil
+}
+
+// List 分页列出文件夹内容，parentID为nil表示根目录
+//
+// 文件夹排在文件之前。按名称的自然排序或语言规则排序无法使用数据库索引，
+// 子项不超过 MaxCollatedEntries 时在内存中排序后分页，否则按索引的字节顺序分页查询
+func (s *treeService) List(ctx context.Context, userID uint, parentID *uint, options ListOptions) ([]*models.File, int64, error) {
+	if userID == 0 {
+		return nil, 0, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "用户ID不能为空")
+	}
+	if parentID != nil {
+		folder, err := s.getOwnedActive(ctx, userID, *parentID)
+		if err != nil {
+			return nil, 0, err
+		}
+		if !folder.IsFolder {
+			return nil, 0, pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "只能列出文件夹的内容")
+		}
+	}
+	if options.Page < 1 {
+		options.Page = 1
+	}
+
+	byName := options.SortBy == "" || options.SortBy == filerepo.ContentsOrderName
+	if byName && (options.Collation == utils.CollationNatural || options.Collation == utils.CollationLocale) {
+		files, total, ok, err := s.listCollated(ctx, userID, parentID, options)
+		if err != nil {
+			return nil, 0, fmt.Errorf("获取文件列表失败: %w", err)
+		}
+		if ok {
+			return files, total, nil
+		}
+	}
+
+	order := filerepo.ContentsOrder{Column: options.SortBy, Desc: options.Desc}
+	files, total, err := s.folderRepo.ListContents(ctx, userID, parentID, options.filter(), order, (options.Page-1)*options.PageSize, options.PageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("获取文件列表失败: %w", err)
+	}
+	return files, total, nil
+}
+
+// listCollated 读取文件夹全部子项的名称，按排序规则排序后查询当前页
+//
+// 子项超过上限时返回false，由调用方按数据库索引顺序查询
+func (s *treeService) listCollated(ctx context.Context, userID uint, parentID *uint, options ListOptions) ([]*models.File, int64, bool, error) {
+	entries, err := s.folderRepo.ListContentNames(ctx, userID, parentID, options.filter(), s.options.MaxCollatedEntries+1)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	if len(entries) > s.options.MaxCollatedEntries {
+		s.logger.Debug("Folder too large for collated sort, using index order",
+			zap.Uint("user_id", userID),
+			zap.String("collation", options.Collation),
+			zap.Int("limit", s.options.MaxCollatedEntries))
+		return nil, 0, false, nil
+	}
+
+	compare := utils.NewNameComparator(options.Collation, options.Locale)
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.IsFolder != b.IsFolder {
+			return a.IsFolder
+		}
+		result := compare(a.Name, b.Name)
+		if result == 0 {
+			result = cmp.Compare(a.ID, b.ID)
+		}
+		if options.Desc {
+			return result > 0
+		}
+		return result < 0
+	})
+
+	total := int64(len(entries))
+	offset := (options.Page - 1) * options.PageSize
+	if offset >= len(entries) {
+		return []*models.File{}, total, true, nil
+	}
+	entries = entries[offset:min(offset+options.PageSize, len(entries))]
+
+	ids := make([]uint, len(entries))
+	for i, entry := range entries {
+		ids[i] = entry.ID
+	}
+	found, err := s.folderRepo.FindContents(ctx, userID, ids, options.IncludeUploading)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	byID := make(map[uint]*models.File, len(found))
+	for _, f := range found {
+		byID[f.ID] = f
+	}
+	// 按排序后的顺序返回，期间被删除的子项跳过
+	files := make([]*models.File, 0, len(ids))
+	for _, id := range ids {
+		if f, ok := byID[id]; ok {
+			files = append(files, f)
+		}
+	}
+	return files, total, true, nil
+}
+
+// CreateFolder 在目标文件夹下新建子文件夹，parentID为nil表示根目录，同名时返回冲突错误
+func (s *treeService) CreateFolder(ctx context.Context, userID uint, parentID *uint, name string) (*models.File, error) {
+	if userID == 0 {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "用户ID不能为空")
+	}
+	name, err := utils.NormalizeFileName(name)
+	if err != nil {
+		return nil, err
+	}
+	parentPath, err := s.resolveTarget(ctx, userID, &models.File{}, parentID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.ensureNameFree(ctx, userID, parentID, name, 0); err != nil {
+		return nil, err
+	}
+	if err := checkFolderLimits(ctx, s.folders, userID, parentID, parentPath, 1); err != nil {
+		return nil, err
+	}
+
+	folder := &models.File{
+		UserID:       userID,
+		ParentID:     parentID,
+		Name:         name,
+		Path:         parentPath,
+		IsFolder:     true,
+		StorageClass: storage.StorageClassStandard,
+		AccessLevel:  "private",
+		Status:       "active",
+		UploadStatus: "completed",
+	}
+	if err := s.fileRepo.Create(ctx, folder); err != nil {
+		return nil, fmt.Errorf("创建文件夹失败: %w", err)
+	}
+	if parentID != nil {
+		s.invalidateChecksums(ctx, *parentID)
+	}
+
+	s.logger.Info("Folder created",
+		zap.Uint("user_id", userID),
+		zap.Uint("file_id", folder.ID),
+		zap.String("path", folder.GetFullPath()))
+	return folder, nil
+}
+
+// relocate 将根项目移动到新位置并更新全部子项路径，subtree 为根项目的子树(含根项目)
+//
+// 变更前后分别使原父链和新父链上的文件夹校验和失效
+func (s *treeService) relocate(ctx context.Context, root *models.File, subtree []*models.File, parentID *uint, parentPath, name string) error {
+	descendants := make([]*models.File, 0, len(subtree))
+	for _, file := range subtree {
+		if file.ID != root.ID {
+			descendants = append(descendants, file)
+		}
+	}
+
+	s.invalidateChecksums(ctx, root.ID)
+
+	oldPath := root.GetFullPath()
+	root.Name, root.ParentID, root.Path = name, parentID, parentPath
+	if !root.IsFolder {
+		root.Extension = fileExtension(name)
+	}
+	newPath := root.GetFullPath()
+	for _, file := range descendants {
+		file.Path = newPath + strings.TrimPrefix(file.Path, oldPath)
+	}
+
+	if err := s.folderRepo.MoveTree(ctx, root, descendants); err != nil {
+		return fmt.Errorf("更新文件位置失败: %w", err)
+	}
+
+	s.invalidateChecksums(ctx, root.ID)
+	ids := make([]uint, 0, len(subtree))
+	ids = append(ids, root.ID)
+	for _, file := range descendants {
+		ids = append(ids, file.ID)
+	}
+	s.evictCache(ids)
+	return nil
+}
+
+// getOwnedActive 获取属于指定用户的可用文件
+func (s *treeService) getOwnedActive(ctx context.Context, userID, fileID uint) (*models.File, error) {
+	if userID == 0 || fileID == 0 {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "用户ID和文件ID不能为空")
+	}
+
+	file, err := s.fileRepo.GetByID(ctx, fileID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "文件不存在")
+		}
+		return nil, fmt.Errorf("获取文件失败: %w", err)
+	}
+	if file.UserID != userID {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, "无权访问该文件")
+	}
+	if !file.IsActive() {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "文件当前不可操作")
+	}
+	return file, nil
+}
+
+// resolveTarget 校验目标文件夹并返回其完整路径，目标为根目录时返回 "/"
+//
+// 文件夹不能移动或复制到自身及其子文件夹中：沿目标文件夹的父链向上查找，
+// 遇到源文件夹即构成循环
+func (s *treeService) resolveTarget(ctx context.Context, userID uint, root *models.File, targetParentID *uint) (string, error) {
+	if targetParentID == nil {
+		return "/", nil
+	}
+
+	target, err := s.fileRepo.GetByID(ctx, *targetParentID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "目标文件夹不存在")
+		}
+		return "", fmt.Errorf("获取目标文件夹失败: %w", err)
+	}
+	if target.UserID != userID {
+		return "", pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, "无权访问目标文件夹")
+	}
+	if !target.IsFolder || !target.IsActive() {
+		return "", pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "目标必须是有效的文件夹")
+	}
+
+	if root.IsFolder {
+		current := target
+		for depth := 0; ; depth++ {
+			if current.ID == root.ID {
+				return "", pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "不能将文件夹移动或复制到其自身或子文件夹中")
+			}
+			if current.ParentID == nil {
+				break
+			}
+			if depth >= maxFolderDepth {
+				return "", pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "目标文件夹层级过深")
+			}
+			current, err = s.fileRepo.GetByID(ctx, *current.ParentID)
+			if err != nil {
+				return "", fmt.Errorf("获取目标文件夹的上级失败: %w", err)
+			}
+		}
+	}
+	return target.GetFullPath(), nil
+}
+
+// ensureNameFree 检查目标文件夹下没有同名文件
+func (s *treeService) ensureNameFree(ctx context.Context, userID uint, parentID *uint, name string, excludeID uint) error {
+	exists, err := s.folderRepo.NameExists(ctx, userID, parentID, name, excludeID)
+	if err != nil {
+		return fmt.Errorf("检查同名文件失败: %w", err)
+	}
+	if exists {
+		return pkgErrors.WrapError(pkgErrors.ErrResourceExists, "目标文件夹中已存在同名文件")
+	}
+	return nil
+}
+
+// checkQuota 检查用户剩余存储空间
+func (s *treeService) checkQuota(ctx context.Context, userID uint, size int64) error {
+	if size <= 0 {
+		return nil
+	}
+	user, err := s.accounts.GetByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("获取用户失败: %w", err)
+	}
+	if user.StorageQuota > 0 && !user.HasStorageSpace(size) {
+		return pkgErrors.WrapError(pkgErrors.ErrQuotaExceeded, "存储空间不足")
+	}
+	return nil
+}
+
+// copyObjects 为每个有存储对象的文件复制一份对象，返回已复制的对象路径
+func (s *treeService) copyObjects(ctx context.Context, userID uint, sources, copies []*models.File) ([]string, error) {
+	month := s.now().UTC().Format("2006/01")
+	var copied []string
+	for i, source := range sources {
+		if source.IsFolder || source.StoragePath == nil {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return copied, err
+		}
+
+		copies[i].UUID = basemodels.GenerateUUID()
+		key := path.Join(s.options.KeyPrefix, strconv.FormatUint(uint64(userID), 10), month, copies[i].UUID)
+		if err := s.copyObject(ctx, *source.StoragePath, key, source.Size); err != nil {
+			return copied, fmt.Errorf("复制文件 %s 失败: %w", source.Name, err)
+		}
+		copies[i].StoragePath = &key
+		copied = append(copied, key)
+	}
+	return copied, nil
+}
+
+// copyObject 复制单个存储对象
+func (s *treeService) copyObject(ctx context.Context, src, dst string, size int64) error {
+	reader, err := s.store.Open(ctx, src)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	return s.store.Put(ctx, dst, reader, size)
+}
+
+// deleteObjects 删除复制失败时已写入的存储对象，失败只记录日志
+func (s *treeService) deleteObjects(ctx context.Context, keys []string) {
+	for _, key := range keys {
+		if err := s.store.Delete(ctx, key); err != nil {
+			s.logger.Warn("Failed to delete copied object",
+				zap.String("path", key),
+				zap.Error(err))
+		}
+	}
+}
+
+// invalidateChecksums 使父链上的文件夹校验和失效，失败只记录日志
+func (s *treeService) invalidateChecksums(ctx context.Context, fileID uint) {
+	if s.checksums == nil {
+		return
+	}
+	if err := s.checksums.InvalidateChecksums(ctx, fileID); err != nil {
+		s.logger.Warn("Failed to invalidate folder checksums",
+			zap.Uint("file_id", fileID),
+			zap.Error(err))
+	}
+}
+
+// evictCache 清除文件的信息、预览和下载缓存，失败只记录日志
+func (s *treeService) evictCache(ids []uint) {
+	if s.cache == nil || len(ids) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(ids)*3)
+	for _, id := range ids {
+		fileID := strconv.FormatUint(uint64(id), 10)
+		keys = append(keys, s.keys.FileInfo(fileID), s.keys.FilePreview(fileID), s.keys.FileDownload(fileID))
+	}
+	if err := s.cache.Delete(keys...); err != nil {
+		s.logger.Warn("Failed to evict file cache",
+			zap.Int("files", len(ids)),
+			zap.Error(err))
+	}
+}
+
+// selectCopySources 从子树中选出需要复制的文件，并计算每个文件的父项下标
+//
+// subtree 按路径长度排序，父项在子项之前；非可用状态的文件及其子项被跳过
+func selectCopySources(root *models.File, subtree []*models.File) ([]*models.File, []int) {
+	sources := []*models.File{root}
+	parents := []int{-1}
+	index := map[uint]int{root.ID: 0}
+	for _, file := range subtree {
+		if file.ID == root.ID || file.ParentID == nil || !file.IsActive() {
+			continue
+		}
+		parent, ok := index[*file.ParentID]
+		if !ok {
+			continue
+		}
+		if file.IsFolder {
+			index[file.ID] = len(sources)
+		}
+		sources = append(sources, file)
+		parents = append(parents, parent)
+	}
+	return sources, parents
+}
+
+// newFileCopy 生成文件记录的副本，不包含主键、统计、归档状态和派生资源
+//
+// 内容相同，病毒扫描结果随副本保留
+func newFileCopy(source *models.File) *models.File {
+	return &models.File{
+		UserID:        source.UserID,
+		ParentID:      source.ParentID,
+		Name:          source.Name,
+		Path:          source.Path,
+		IsFolder:      source.IsFolder,
+		MimeType:      source.MimeType,
+		Extension:     source.Extension,
+		Size:          source.Size,
+		Hash:          source.Hash,
+		HashType:      source.HashType,
+		StorageType:   source.StorageType,
+		StorageBucket: source.StorageBucket,
+		StorageClass:  storage.StorageClassStandard,
+		IsEncrypted:   source.IsEncrypted,
+		EncryptionKey: source.EncryptionKey,
+		AccessLevel:   "private",
+		Status:        "active",
+		UploadStatus:  "completed",
+		ScanStatus:    source.ScanStatus,
+		Metadata:      source.Metadata,
+		Tags:          source.Tags,
+		Description:   source.Description,
+	}
+}
+
+// fileExtension 返回文件名的小写扩展名(不含点)，没有扩展名时返回nil
+func fileExtension(name string) *string {
+	ext := strings.TrimPrefix(path.Ext(name), ".")
+	if ext == "" {
+		return nil
+	}
+	ext = strings.ToLower(ext)
+	return &ext
+}
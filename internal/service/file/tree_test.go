@@ -0,0 +1,546 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/storage"
+	"cloudpan/internal/pkg/utils"
+	filerepo "cloudpan/internal/repository/file"
+	"cloudpan/internal/repository/models"
+)
+
+// memoryFolders 内存文件夹树仓储
+type memoryFolders struct {
+	memoryFileRepository
+	nextID    uint
+	createErr error
+}
+
+func (m *memoryFolders) ListSubtree(_ context.Context, root *models.File) ([]*models.File, error) {
+	files := []*models.File{root}
+	if !root.IsFolder {
+		return files, nil
+	}
+	full := root.GetFullPath()
+	for _, f := range m.files {
+		if f.ID != root.ID && f.UserID == root.UserID && (f.Path == full || strings.HasPrefix(f.Path, full+"/")) {
+			files = append(files, f)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		if len(files[i].Path) != len(files[j].Path) {
+			return len(files[i].Path) < len(files[j].Path)
+		}
+		return files[i].ID < files[j].ID
+	})
+	return files, nil
+}
+
+func (m *memoryFolders) NameExists(_ context.Context, userID uint, parentID *uint, name string, excludeID uint) (bool, error) {
+	for _, f := range m.files {
+		if f.UserID == userID && f.ID != excludeID && f.Name == name && sameParent(f.ParentID, parentID) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *memoryFolders) MoveTree(context.Context, *models.File, []*models.File) error {
+	// 文件由服务直接修改，内存中无需另行保存
+	return nil
+}
+
+func (m *memoryFolders) CreateTree(_ context.Context, files []*models.File, parents []int) error {
+	if m.createErr != nil {
+		return m.createErr
+	}
+	for i, f := range files {
+		if parents[i] >= 0 {
+			parentID := files[parents[i]].ID
+			f.ParentID = &parentID
+		}
+		m.nextID++
+		f.ID = m.nextID
+		m.files[f.ID] = f
+	}
+	return nil
+}
+
+func (m *memoryFolders) ListContents(_ context.Context, userID uint, parentID *uint, filter filerepo.ContentsFilter, order filerepo.ContentsOrder, offset, limit int) ([]*models.File, int64, error) {
+	files := m.contents(userID, parentID, filter)
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].IsFolder != files[j].IsFolder {
+			return files[i].IsFolder
+		}
+		less := files[i].Name < files[j].Name
+		if order.Column == filerepo.ContentsOrderSize {
+			less = files[i].Size < files[j].Size
+		}
+		if order.Desc {
+			return !less
+		}
+		return less
+	})
+	total := int64(len(files))
+	if offset >= len(files) {
+		return nil, total, nil
+	}
+	files = files[offset:]
+	if len(files) > limit {
+		files = files[:limit]
+	}
+	return files, total, nil
+}
+
+func (m *memoryFolders) ListContentNames(_ context.Context, userID uint, parentID *uint, filter filerepo.ContentsFilter, limit int) ([]*models.File, error) {
+	files := m.contents(userID, parentID, filter)
+	if len(files) > limit {
+		files = files[:limit]
+	}
+	return files, nil
+}
+
+func (m *memoryFolders) FindContents(_ context.Context, userID uint, ids []uint, includeUploading bool) ([]*models.File, error) {
+	var files []*models.File
+	for _, id := range ids {
+		if f, ok := m.files[id]; ok && f.UserID == userID && (f.IsActive() || includeUploading && f.IsUploadPlaceholder()) {
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+func (m *memoryFolders) CountChildren(_ context.Context, userID uint, parentID *uint) (int64, error) {
+	var count int64
+	for _, f := range m.files {
+		if f.UserID == userID && !f.DeletedAt.Valid && sameParent(f.ParentID, parentID) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *memoryFolders) ListCrowdedFolders(_ context.Context, minChildren int64, limit int) ([]filerepo.FolderChildCount, error) {
+	type key struct {
+		userID   uint
+		parentID uint
+		root     bool
+	}
+	counts := make(map[key]*filerepo.FolderChildCount)
+	for _, f := range m.files {
+		if f.DeletedAt.Valid {
+			continue
+		}
+		k := key{userID: f.UserID, root: f.ParentID == nil}
+		if f.ParentID != nil {
+			k.parentID = *f.ParentID
+		}
+		if counts[k] == nil {
+			counts[k] = &filerepo.FolderChildCount{UserID: f.UserID, ParentID: f.ParentID}
+		}
+		counts[k].Children++
+	}
+	var result []filerepo.FolderChildCount
+	for _, c := range counts {
+		if c.Children >= minChildren {
+			result = append(result, *c)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Children > result[j].Children })
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+func (m *memoryFolders) ListDeepFolders(_ context.Context, minDepth, limit int) ([]*models.File, error) {
+	var folders []*models.File
+	for _, f := range m.files {
+		if f.IsFolder && f.IsActive() && pathDepth(f.Path)+1 >= minDepth {
+			folders = append(folders, f)
+		}
+	}
+	sort.Slice(folders, func(i, j int) bool {
+		if di, dj := pathDepth(folders[i].Path), pathDepth(folders[j].Path); di != dj {
+			return di > dj
+		}
+		return folders[i].ID < folders[j].ID
+	})
+	if len(folders) > limit {
+		folders = folders[:limit]
+	}
+	return folders, nil
+}
+
+// contents 文件夹下的可用子项，顺序不确定
+func (m *memoryFolders) contents(userID uint, parentID *uint, filter filerepo.ContentsFilter) []*models.File {
+	var files []*models.File
+	for _, f := range m.files {
+		listed := f.IsActive() || filter.IncludeUploading && f.IsUploadPlaceholder()
+		if f.UserID != userID || !listed || !sameParent(f.ParentID, parentID) {
+			continue
+		}
+		if !filter.IsZero() {
+			processing := f.Processing()
+			if processing == nil ||
+				(filter.ScanStatus != "" && processing.Scan != filter.ScanStatus) ||
+				(filter.PreviewStatus != "" && processing.Preview != filter.PreviewStatus) {
+				continue
+			}
+		}
+		files = append(files, f)
+	}
+	return files
+}
+
+func (m *memoryFolders) Create(_ context.Context, f *models.File) error {
+	m.nextID++
+	f.ID = m.nextID
+	m.files[f.ID] = f
+	return nil
+}
+
+// trackingStore 记录删除操作的存储
+type trackingStore struct {
+	storage.Storage
+	deleted []string
+}
+
+func (s *trackingStore) Delete(ctx context.Context, path string) error {
+	s.deleted = append(s.deleted, path)
+	return s.Storage.Delete(ctx, path)
+}
+
+// recordingCache 记录被删除的缓存键
+type recordingCache struct {
+	keys []string
+}
+
+func (c *recordingCache) Delete(keys ...string) error {
+	c.keys = append(c.keys, keys...)
+	return nil
+}
+
+type treeFixture struct {
+	service  *treeService
+	folders  *memoryFolders
+	accounts *MockStorageAccountStore
+	store    *trackingStore
+	cache    *recordingCache
+}
+
+// newTreeFixture 构建目录树：/docs(1) 下有 a.txt(2) 和 /docs/sub(3)，/docs/sub 下有 b.txt(4)；/archive(5) 为空文件夹
+func newTreeFixture(t *testing.T) *treeFixture {
+	local, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	store := &trackingStore{Storage: local}
+	ctx := context.Background()
+
+	docs := newTestFile(1, 7, nil, "docs", true)
+	docs.Path = "/"
+	a := newTestFile(2, 7, uintPtr(1), "a.txt", false)
+	a.Path, a.Size, a.StoragePath = "/docs", 5, strPtr("objects/a")
+	require.NoError(t, store.Put(ctx, "objects/a", strings.NewReader("hello"), 5))
+	sub := newTestFile(3, 7, uintPtr(1), "sub", true)
+	sub.Path = "/docs"
+	b := newTestFile(4, 7, uintPtr(3), "b.txt", false)
+	b.Path, b.Size, b.StoragePath = "/docs/sub", 3, strPtr("objects/b")
+	require.NoError(t, store.Put(ctx, "objects/b", strings.NewReader("bye"), 3))
+	archive := newTestFile(5, 7, nil, "archive", true)
+	archive.Path = "/"
+
+	folders := &memoryFolders{memoryFileRepository: memoryFileRepository{files: make(map[uint]*models.File)}, nextID: 100}
+	for _, f := range []*models.File{docs, a, sub, b, archive} {
+		f.Status = "active"
+		folders.files[f.ID] = f
+	}
+
+	accounts := new(MockStorageAccountStore)
+	cacheDeleter := &recordingCache{}
+	service := NewTreeService(folders, folders, accounts, store, nil, cacheDeleter, nil, TreeOptions{}, nil).(*treeService)
+	service.now = func() time.Time { return time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC) }
+	return &treeFixture{service: service, folders: folders, accounts: accounts, store: store, cache: cacheDeleter}
+}
+
+func TestTreeService_Rename(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("folder propagates path", func(t *testing.T) {
+		f := newTreeFixture(t)
+		renamed, err := f.service.Rename(ctx, 7, 1, " reports ")
+		require.NoError(t, err)
+		assert.Equal(t, "reports", renamed.Name)
+		assert.Equal(t, "/reports", f.folders.files[2].Path)
+		assert.Equal(t, "/reports/sub", f.folders.files[4].Path)
+		assert.Contains(t, f.cache.keys, "file:4")
+	})
+
+	t.Run("file updates extension", func(t *testing.T) {
+		f := newTreeFixture(t)
+		renamed, err := f.service.Rename(ctx, 7, 2, "a.PDF")
+		require.NoError(t, err)
+		require.NotNil(t, renamed.Extension)
+		assert.Equal(t, "pdf", *renamed.Extension)
+	})
+
+	t.Run("name taken", func(t *testing.T) {
+		f := newTreeFixture(t)
+		_, err := f.service.Rename(ctx, 7, 2, "sub")
+		assert.ErrorIs(t, err, pkgErrors.ErrResourceExists)
+	})
+
+	t.Run("invalid name", func(t *testing.T) {
+		f := newTreeFixture(t)
+		_, err := f.service.Rename(ctx, 7, 2, "../x")
+		assert.Error(t, err)
+		assert.Equal(t, "a.txt", f.folders.files[2].Name)
+	})
+}
+
+func TestTreeService_Move(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("folder into another folder", func(t *testing.T) {
+		f := newTreeFixture(t)
+		moved, err := f.service.Move(ctx, 7, 1, uintPtr(5))
+		require.NoError(t, err)
+		assert.Equal(t, "/archive/docs", moved.GetFullPath())
+		assert.Equal(t, "/archive/docs", f.folders.files[2].Path)
+		assert.Equal(t, "/archive/docs/sub", f.folders.files[4].Path)
+		assert.Equal(t, uint(3), *f.folders.files[4].ParentID, "子项的父文件夹不变")
+	})
+
+	t.Run("into own descendant", func(t *testing.T) {
+		f := newTreeFixture(t)
+		_, err := f.service.Move(ctx, 7, 1, uintPtr(3))
+		assert.True(t, pkgErrors.IsPermissionError(err))
+		_, err = f.service.Move(ctx, 7, 1, uintPtr(1))
+		assert.True(t, pkgErrors.IsPermissionError(err))
+		assert.Equal(t, "/docs/sub", f.folders.files[4].Path)
+	})
+
+	t.Run("to root", func(t *testing.T) {
+		f := newTreeFixture(t)
+		moved, err := f.service.Move(ctx, 7, 4, nil)
+		require.NoError(t, err)
+		assert.Nil(t, moved.ParentID)
+		assert.Equal(t, "/b.txt", moved.GetFullPath())
+	})
+
+	t.Run("target is a file", func(t *testing.T) {
+		f := newTreeFixture(t)
+		_, err := f.service.Move(ctx, 7, 4, uintPtr(2))
+		assert.True(t, pkgErrors.IsValidationError(err))
+	})
+
+	t.Run("other user's folder", func(t *testing.T) {
+		f := newTreeFixture(t)
+		_, err := f.service.Move(ctx, 8, 1, uintPtr(5))
+		assert.True(t, pkgErrors.IsPermissionError(err))
+	})
+}
+
+func TestTreeService_Copy(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("folder with contents", func(t *testing.T) {
+		f := newTreeFixture(t)
+		f.accounts.On("GetByID", mock.Anything, uint(7)).Return(&models.User{StorageQuota: 100, StorageUsed: 10}, nil)
+		f.accounts.On("UpdateStorageUsed", mock.Anything, uint(7), int64(8)).Return(nil).Once()
+
+		copied, err := f.service.Copy(ctx, 7, 1, uintPtr(5))
+		require.NoError(t, err)
+		assert.Equal(t, "/archive/docs", copied.GetFullPath())
+		require.Len(t, f.folders.files, 9)
+
+		var copiedB *models.File
+		for _, file := range f.folders.files {
+			if file.ID > 100 && file.Name == "b.txt" {
+				copiedB = file
+			}
+		}
+		require.NotNil(t, copiedB)
+		assert.Equal(t, "/archive/docs/sub", copiedB.Path)
+		parent := f.folders.files[*copiedB.ParentID]
+		assert.Equal(t, "sub", parent.Name)
+		assert.Equal(t, copied.ID, *parent.ParentID)
+
+		require.NotNil(t, copiedB.StoragePath)
+		assert.NotEqual(t, "objects/b", *copiedB.StoragePath)
+		reader, err := f.store.Open(ctx, *copiedB.StoragePath)
+		require.NoError(t, err)
+		content, _ := io.ReadAll(reader)
+		reader.Close()
+		assert.Equal(t, "bye", string(content))
+		f.accounts.AssertExpectations(t)
+	})
+
+	t.Run("same folder gets numbered name", func(t *testing.T) {
+		f := newTreeFixture(t)
+		f.accounts.On("GetByID", mock.Anything, uint(7)).Return(&models.User{}, nil)
+		f.accounts.On("UpdateStorageUsed", mock.Anything, uint(7), int64(5)).Return(nil)
+
+		copied, err := f.service.Copy(ctx, 7, 2, uintPtr(1))
+		require.NoError(t, err)
+		assert.Equal(t, "a (1).txt", copied.Name)
+	})
+
+	t.Run("quota exceeded", func(t *testing.T) {
+		f := newTreeFixture(t)
+		f.accounts.On("GetByID", mock.Anything, uint(7)).Return(&models.User{StorageQuota: 10, StorageUsed: 5}, nil)
+
+		_, err := f.service.Copy(ctx, 7, 1, nil)
+		assert.ErrorIs(t, err, pkgErrors.ErrQuotaExceeded)
+		assert.Len(t, f.folders.files, 5)
+	})
+
+	t.Run("records failure removes copied objects", func(t *testing.T) {
+		f := newTreeFixture(t)
+		f.folders.createErr = errors.New("db down")
+		f.accounts.On("GetByID", mock.Anything, uint(7)).Return(&models.User{}, nil)
+
+		_, err := f.service.Copy(ctx, 7, 4, nil)
+		require.Error(t, err)
+		require.Len(t, f.store.deleted, 1)
+		exists, err := f.store.Exists(ctx, f.store.deleted[0])
+		require.NoError(t, err)
+		assert.False(t, exists)
+	})
+}
+
+func TestTreeService_List(t *testing.T) {
+	ctx := context.Background()
+	f := newTreeFixture(t)
+
+	files, total, err := f.service.List(ctx, 7, uintPtr(1), ListOptions{Page: 1, PageSize: 10})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	require.Len(t, files, 2)
+	assert.Equal(t, "sub", files[0].Name, "文件夹排在文件之前")
+
+	files, total, err = f.service.List(ctx, 7, nil, ListOptions{Page: 2, PageSize: 1})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	require.Len(t, files, 1)
+	assert.Equal(t, "docs", files[0].Name)
+
+	files, _, err = f.service.List(ctx, 7, uintPtr(1), ListOptions{Page: 1, PageSize: 10, SortBy: "size", Desc: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sub", "a.txt"}, []string{files[0].Name, files[1].Name})
+
+	// 按处理状态筛选时只返回文件
+	files[1].ScanStatus = models.ScanStatusClean
+	files, total, err = f.service.List(ctx, 7, uintPtr(1), ListOptions{Page: 1, PageSize: 10, ScanStatus: models.ScanStatusClean})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Equal(t, "a.txt", files[0].Name)
+	_, total, err = f.service.List(ctx, 7, uintPtr(1), ListOptions{Page: 1, PageSize: 10, PreviewStatus: models.PreviewStatusReady})
+	require.NoError(t, err)
+	assert.Zero(t, total)
+
+	_, _, err = f.service.List(ctx, 7, uintPtr(2), ListOptions{Page: 1, PageSize: 10})
+	assert.True(t, pkgErrors.IsValidationError(err))
+	_, _, err = f.service.List(ctx, 8, uintPtr(1), ListOptions{Page: 1, PageSize: 10})
+	assert.True(t, pkgErrors.IsPermissionError(err))
+}
+
+func TestTreeService_ListUploading(t *testing.T) {
+	ctx := context.Background()
+	f := newTreeFixture(t)
+	uploading := newTestFile(20, 7, uintPtr(5), "disk.iso", false)
+	uploading.Status, uploading.UploadStatus = models.FileStatusUploading, models.UploadStatusUploading
+	direct := newTestFile(21, 7, uintPtr(5), "photo.png", false)
+	direct.Status, direct.UploadStatus = models.FileStatusUploading, models.UploadStatusPending
+	f.folders.files[uploading.ID] = uploading
+	f.folders.files[direct.ID] = direct
+
+	_, total, err := f.service.List(ctx, 7, uintPtr(5), ListOptions{Page: 1, PageSize: 10})
+	require.NoError(t, err)
+	assert.Zero(t, total, "默认不包含上传中的文件")
+
+	for _, collation := range []string{"", utils.CollationNatural} {
+		files, total, err := f.service.List(ctx, 7, uintPtr(5), ListOptions{Page: 1, PageSize: 10, Collation: collation, IncludeUploading: true})
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), total, "只包含分片上传的占位文件")
+		require.Len(t, files, 1)
+		assert.Equal(t, "disk.iso", files[0].Name)
+	}
+
+	// 按处理状态筛选时不包含占位文件
+	_, total, err = f.service.List(ctx, 7, uintPtr(5), ListOptions{Page: 1, PageSize: 10, ScanStatus: models.ScanStatusPending, IncludeUploading: true})
+	require.NoError(t, err)
+	assert.Zero(t, total)
+}
+
+func TestTreeService_ListCollated(t *testing.T) {
+	ctx := context.Background()
+	f := newTreeFixture(t)
+	for i, name := range []string{"file10.txt", "file2.txt", "张三.txt", "李四.txt", "File1.txt"} {
+		file := newTestFile(uint(20+i), 7, uintPtr(5), name, false)
+		file.Status = "active"
+		f.folders.files[file.ID] = file
+	}
+	names := func(files []*models.File) []string {
+		result := make([]string, len(files))
+		for i, file := range files {
+			result[i] = file.Name
+		}
+		return result
+	}
+
+	files, total, err := f.service.List(ctx, 7, uintPtr(5), ListOptions{Page: 1, PageSize: 3, Collation: utils.CollationNatural})
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), total)
+	assert.Equal(t, []string{"File1.txt", "file2.txt", "file10.txt"}, names(files))
+
+	files, _, err = f.service.List(ctx, 7, uintPtr(5), ListOptions{Page: 1, PageSize: 10, Collation: utils.CollationLocale, Locale: "zh-CN"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"File1.txt", "file2.txt", "file10.txt", "李四.txt", "张三.txt"}, names(files), "中文按拼音排序")
+
+	files, _, err = f.service.List(ctx, 7, uintPtr(5), ListOptions{Page: 2, PageSize: 3, Desc: true, Collation: utils.CollationNatural})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"file2.txt", "File1.txt"}, names(files))
+
+	// 子项超过上限时按数据库索引的字节顺序分页
+	f.service.options.MaxCollatedEntries = 2
+	files, total, err = f.service.List(ctx, 7, uintPtr(5), ListOptions{Page: 1, PageSize: 3, Collation: utils.CollationNatural})
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), total)
+	assert.Equal(t, []string{"File1.txt", "file10.txt", "file2.txt"}, names(files))
+}
+
+func TestTreeService_CreateFolder(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("nested", func(t *testing.T) {
+		f := newTreeFixture(t)
+		folder, err := f.service.CreateFolder(ctx, 7, uintPtr(3), " drafts ")
+		require.NoError(t, err)
+		assert.True(t, folder.IsFolder)
+		assert.Equal(t, "/docs/sub/drafts", folder.GetFullPath())
+		assert.Same(t, folder, f.folders.files[folder.ID])
+	})
+
+	t.Run("name taken", func(t *testing.T) {
+		f := newTreeFixture(t)
+		_, err := f.service.CreateFolder(ctx, 7, nil, "docs")
+		assert.ErrorIs(t, err, pkgErrors.ErrResourceExists)
+	})
+
+	t.Run("parent is a file", func(t *testing.T) {
+		f := newTreeFixture(t)
+		_, err := f.service.CreateFolder(ctx, 7, uintPtr(2), "x")
+		assert.True(t, pkgErrors.IsValidationError(err))
+	})
+}
@@ -0,0 +1,187 @@
+package file
+
+import (
+	"sync"
+	"time"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+)
+
+// 上传进度阶段
+const (
+	UploadPhaseUploading = "uploading" // 接收分片中
+	UploadPhaseMerging   = "merging"   // 服务端合并分片中
+	UploadPhaseScanning  = "scanning"  // 安全扫描中
+	UploadPhaseCompleted = "completed" // 上传完成
+	UploadPhaseFailed    = "failed"    // 上传失败
+)
+
+const (
+	// progressSubscriberBuffer 每个订阅者的事件缓冲，满时丢弃最旧的事件
+	progressSubscriberBuffer = 16
+	// progressRetention 上传结束后保留进度快照的时间，便于迟到的客户端获取最终状态
+	progressRetention = 5 * time.Minute
+)
+
+// UploadProgress 服务端观察到的上传进度
+type UploadProgress struct {
+	UploadID       string    `json:"upload_id"`         // 上传任务ID
+	UserID         uint      `json:"-"`                 // 所属用户ID
+	Phase          string    `json:"phase"`             // 当前阶段
+	ReceivedChunks int       `json:"received_chunks"`   // 已接收分片数
+	TotalChunks    int       `json:"total_chunks"`      // 总分片数
+	ReceivedBytes  int64     `json:"received_bytes"`    // 已接收字节数
+	TotalBytes     int64     `json:"total_bytes"`       // 文件总字节数
+	Message        string    `json:"message,omitempty"` // 附加信息(如失败原因)
+	UpdatedAt      time.Time `json:"updated_at"`        // 更新时间
+}
+
+// IsFinished 检查上传是否已结束(成功或失败)
+func (p UploadProgress) IsFinished() bool {
+	return p.Phase == UploadPhaseCompleted || p.Phase == UploadPhaseFailed
+}
+
+// progressSession 单个上传任务的进度状态和订阅者
+type progressSession struct {
+	snapshot    UploadProgress
+	subscribers map[int]chan UploadProgress
+	nextID      int
+}
+
+// ProgressHub 上传进度事件中心
+//
+// 上传、合并、扫描等环节通过Publish上报进度，SSE等推送通道通过Subscribe订阅。
+// 进度仅保存在当前实例内存中，上传结束后保留一段时间再清理
+//
+// 使用示例：
+//
+//	hub := NewProgressHub()
+//	hub.Publish(UploadProgress{UploadID: id, UserID: uid, Phase: UploadPhaseUploading})
+//	events, cancel, err := hub.Subscribe(id, uid)
+//	defer cancel()
+type ProgressHub struct {
+	mu       sync.Mutex
+	sessions map[string]*progressSession
+}
+
+// NewProgressHub 创建上传进度事件中心
+func NewProgressHub() *ProgressHub {
+	return &ProgressHub{
+		sessions: make(map[string]*progressSession),
+	}
+}
+
+// Publish 发布上传进度，并推送给所有订阅者
+func (h *ProgressHub) Publish(progress UploadProgress) {
+	if progress.UploadID == "" {
+		return
+	}
+	if progress.UpdatedAt.IsZero() {
+		progress.UpdatedAt = time.Now()
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	session, exists := h.sessions[progress.UploadID]
+	if !exists {
+		session = &progressSession{subscribers: make(map[int]chan UploadProgress)}
+		h.sessions[progress.UploadID] = session
+	}
+	wasFinished := session.snapshot.IsFinished()
+	session.snapshot = progress
+
+	for _, ch := range session.subscribers {
+		deliverProgress(ch, progress)
+	}
+
+	if progress.IsFinished() && !wasFinished {
+		uploadID := progress.UploadID
+		time.AfterFunc(progressRetention, func() {
+			h.Remove(uploadID)
+		})
+	}
+}
+
+// Subscribe 订阅上传进度
+//
+// 订阅后立即收到当前快照；只有上传任务的所有者可以订阅。
+// 返回的cancel函数用于取消订阅，必须调用以释放资源
+func (h *ProgressHub) Subscribe(uploadID string, userID uint) (<-chan UploadProgress, func(), error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	session, exists := h.sessions[uploadID]
+	if !exists {
+		return nil, nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "上传任务不存在")
+	}
+	if session.snapshot.UserID != userID {
+		return nil, nil, pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, "无权查看该上传任务")
+	}
+
+	ch := make(chan UploadProgress, progressSubscriberBuffer)
+	ch <- session.snapshot
+
+	id := session.nextID
+	session.nextID++
+	session.subscribers[id] = ch
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			if current, ok := h.sessions[uploadID]; ok && current == session {
+				if sub, ok := session.subscribers[id]; ok {
+					delete(session.subscribers, id)
+					close(sub)
+				}
+			}
+		})
+	}
+
+	return ch, cancel, nil
+}
+
+// Snapshot 获取上传任务的当前进度
+func (h *ProgressHub) Snapshot(uploadID string) (UploadProgress, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	session, exists := h.sessions[uploadID]
+	if !exists {
+		return UploadProgress{}, false
+	}
+	return session.snapshot, true
+}
+
+// Remove 移除上传任务的进度并关闭所有订阅
+func (h *ProgressHub) Remove(uploadID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	session, exists := h.sessions[uploadID]
+	if !exists {
+		return
+	}
+	for id, ch := range session.subscribers {
+		delete(session.subscribers, id)
+		close(ch)
+	}
+	delete(h.sessions, uploadID)
+}
+
+// deliverProgress 非阻塞投递事件，缓冲已满时丢弃最旧的事件
+func deliverProgress(ch chan UploadProgress, progress UploadProgress) {
+	for {
+		select {
+		case ch <- progress:
+			return
+		default:
+		}
+		select {
+		case <-ch:
+		default:
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package file
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+)
+
+func TestProgressHub_SubscribeReceivesSnapshotAndUpdates(t *testing.T) {
+	hub := NewProgressHub()
+	hub.Publish(UploadProgress{UploadID: "u1", UserID: 7, Phase: UploadPhaseUploading, TotalChunks: 3})
+
+	events, cancel, err := hub.Subscribe("u1", 7)
+	require.NoError(t, err)
+	defer cancel()
+
+	first := <-events
+	assert.Equal(t, UploadPhaseUploading, first.Phase)
+	assert.False(t, first.UpdatedAt.IsZero())
+
+	hub.Publish(UploadProgress{UploadID: "u1", UserID: 7, Phase: UploadPhaseMerging, ReceivedChunks: 3, TotalChunks: 3})
+	second := <-events
+	assert.Equal(t, UploadPhaseMerging, second.Phase)
+	assert.Equal(t, 3, second.ReceivedChunks)
+}
+
+func TestProgressHub_SubscribeAccessControl(t *testing.T) {
+	hub := NewProgressHub()
+	hub.Publish(UploadProgress{UploadID: "u1", UserID: 7, Phase: UploadPhaseUploading})
+
+	_, _, err := hub.Subscribe("u1", 8)
+	assert.True(t, pkgErrors.IsPermissionError(err))
+
+	_, _, err = hub.Subscribe("missing", 7)
+	assert.True(t, pkgErrors.IsNotFoundError(err))
+}
+
+func TestProgressHub_SlowSubscriberKeepsLatest(t *testing.T) {
+	hub := NewProgressHub()
+	hub.Publish(UploadProgress{UploadID: "u1", UserID: 7, Phase: UploadPhaseUploading})
+
+	events, cancel, err := hub.Subscribe("u1", 7)
+	require.NoError(t, err)
+	defer cancel()
+
+	for i := 1; i <= progressSubscriberBuffer*2; i++ {
+		hub.Publish(UploadProgress{UploadID: "u1", UserID: 7, Phase: UploadPhaseUploading, ReceivedChunks: i})
+	}
+
+	var last UploadProgress
+	for len(events) > 0 {
+		last = <-events
+	}
+	assert.Equal(t, progressSubscriberBuffer*2, last.ReceivedChunks)
+}
+
+func TestProgressHub_RemoveClosesSubscribers(t *testing.T) {
+	hub := NewProgressHub()
+	hub.Publish(UploadProgress{UploadID: "u1", UserID: 7, Phase: UploadPhaseCompleted})
+
+	events, cancel, err := hub.Subscribe("u1", 7)
+	require.NoError(t, err)
+
+	<-events
+	hub.Remove("u1")
+	_, open := <-events
+	assert.False(t, open)
+
+	// 移除后再取消订阅不会panic
+	cancel()
+
+	_, exists := hub.Snapshot("u1")
+	assert.False(t, exists)
+}
+
+func BenchmarkProgressHub_Publish(b *testing.B) {
+	hub := NewProgressHub()
+	hub.Publish(UploadProgress{UploadID: "u1", UserID: 7, Phase: UploadPhaseUploading})
+	for i := 0; i < 4; i++ {
+		_, cancel, err := hub.Subscribe("u1", 7)
+		if err != nil {
+			b.Fatal(err)
+		}
+		defer cancel()
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		hub.Publish(UploadProgress{UploadID: "u1", UserID: 7, Phase: UploadPhaseUploading, ReceivedChunks: i})
+	}
+}
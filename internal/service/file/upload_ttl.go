@@ -0,0 +1,314 @@
+package file
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"cloudpan/internal/pkg/config"
+)
+
+// 上传客户端类型，分别统计完成耗时和放弃率
+const (
+	UploadClientWeb     = "web"
+	UploadClientDesktop = "desktop"
+	UploadClientMobile  = "mobile"
+	UploadClientOther   = "other"
+)
+
+// 自适应有效期默认值
+const (
+	DefaultUploadTTLPercentile = 0.95
+	DefaultUploadTTLMultiplier = 2.0
+	DefaultUploadTTLMinSamples = 20
+	uploadTTLSampleWindow      = 200 // 每种客户端保留的最近完成耗时样本数
+)
+
+// UploadTTLOptions 分片上传有效期选项
+type UploadTTLOptions struct {
+	Default     time.Duration // 固定有效期，样本不足或未启用自适应时使用
+	Min         time.Duration // 有效期下限
+	Max         time.Duration // 有效期上限，也是滑动延长的上限
+	Adaptive    bool          // 是否按观察到的完成耗时调整有效期
+	Percentile  float64       // 取完成耗时的分位数
+	Multiplier  float64       // 分位数耗时的倍数
+	MinSamples  int           // 开始调整所需的最少完成样本数
+	IdleTimeout time.Duration // 收到分片后有效期至少保留的时长，0表示不滑动延长
+}
+
+// UploadTTLOptionsFromConfig 从上传有效期配置生成选项
+func UploadTTLOptionsFromConfig(cfg config.UploadTTLConfig) UploadTTLOptions {
+	return UploadTTLOptions{
+		Default:     cfg.Default,
+		Min:         cfg.Min,
+		Max:         cfg.Max,
+		Adaptive:    cfg.Adaptive,
+		Percentile:  cfg.Percentile,
+		Multiplier:  cfg.Multiplier,
+		MinSamples:  cfg.MinSamples,
+		IdleTimeout: cfg.IdleTimeout,
+	}
+}
+
+// withDefaults 填充未配置的选项，上限默认为固定有效期的3倍
+func (o UploadTTLOptions) withDefaults() UploadTTLOptions {
+	if o.Default <= 0 {
+		o.Default = DefaultChunkUploadTTL
+	}
+	if o.Min <= 0 {
+		o.Min = min(time.Hour, o.Default)
+	}
+	if o.Max <= 0 {
+		o.Max = 3 * o.Default
+	}
+	if o.Max < o.Min {
+		o.Max = o.Min
+	}
+	if o.Percentile <= 0 || o.Percentile > 1 {
+		o.Percentile = DefaultUploadTTLPercentile
+	}
+	if o.Multiplier <= 0 {
+		o.Multiplier = DefaultUploadTTLMultiplier
+	}
+	if o.MinSamples <= 0 {
+		o.MinSamples = DefaultUploadTTLMinSamples
+	}
+	if o.IdleTimeout < 0 {
+		o.IdleTimeout = 0
+	}
+	return o
+}
+
+// UploadTTLMetrics 单种客户端的上传有效期指标
+type UploadTTLMetrics struct {
+	ClientType         string  `json:"client_type"`         // 客户端类型(web/desktop/mobile/other)
+	Started            int64   `json:"started"`             // 申请的上传数
+	Completed          int64   `json:"completed"`           // 合并完成的上传数
+	Abandoned          int64   `json:"abandoned"`           // 过期被清理的上传数
+	AbandonRate        float64 `json:"abandon_rate"`        // 已结束的上传中过期被清理的比例
+	Extended           int64   `json:"extended"`            // 上传过程中有效期滑动延长的次数
+	Samples            int     `json:"samples"`             // 参与计算的最近完成样本数
+	MedianDuration     string  `json:"median_duration"`     // 完成耗时中位数
+	PercentileDuration string  `json:"percentile_duration"` // 配置分位数的完成耗时
+	TTL                string  `json:"ttl"`                 // 新上传当前使用的有效期
+	Adaptive           bool    `json:"adaptive"`            // 当前有效期是否由观察数据得出
+}
+
+// uploadClientStats 单种客户端的统计
+type uploadClientStats struct {
+	started   int64
+	completed int64
+	abandoned int64
+	extended  int64
+	durations []time.Duration // 最近完成耗时，环形保存
+	next      int
+}
+
+// UploadTTLTuner 按观察到的上传完成耗时调整分片上传有效期
+//
+// 每种客户端保留最近的完成耗时样本，样本足够时新上传的有效期取分位数耗时乘以倍数并限制在上下限之间，
+// 移动网络等慢速客户端获得更长的有效期，完成较快的客户端放弃的上传更早被清理。
+// 上传过程中持续收到分片时按 IdleTimeout 滑动延长有效期，避免仍在传输的上传被清理。
+// 统计保存在进程内，多实例部署时各实例按自己接收的上传分别调整。并发安全
+//
+// 使用示例：
+//
+//	tuner := NewUploadTTLTuner(UploadTTLOptionsFromConfig(config.AppConfig.Storage.Upload.TTL))
+//	ttl := tuner.TTL(UploadClientMobile)
+//	tuner.ObserveCompleted(UploadClientMobile, 3*time.Hour)
+type UploadTTLTuner struct {
+	mu      sync.Mutex
+	options UploadTTLOptions
+	clients map[string]*uploadClientStats
+}
+
+// NewUploadTTLTuner 创建上传有效期调整器，未配置的选项使用默认值
+func NewUploadTTLTuner(options UploadTTLOptions) *UploadTTLTuner {
+	return &UploadTTLTuner{
+		options: options.withDefaults(),
+		clients: make(map[string]*uploadClientStats),
+	}
+}
+
+// TTL 返回客户端新上传的有效期
+func (t *UploadTTLTuner) TTL(clientType string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ttl, _ := t.ttl(t.stats(clientType))
+	return ttl
+}
+
+// MinTTL 返回有效期下限，清理时据此查找可能过期的占位文件
+func (t *UploadTTLTuner) MinTTL() time.Duration {
+	return min(t.options.Min, t.options.Default)
+}
+
+// Extend 收到分片时计算延长后的过期时间，不需要延长时返回false
+//
+// 剩余有效期不足 IdleTimeout 的一半时延长到当前时间之后的 IdleTimeout，
+// 避免每个分片都写入；延长后不超过申请时间之后的 Max
+func (t *UploadTTLTuner) Extend(clientType string, startedAt, expiresAt, now time.Time) (time.Time, bool) {
+	idle := t.options.IdleTimeout
+	if idle <= 0 || expiresAt.Sub(now) >= idle/2 {
+		return expiresAt, false
+	}
+	extended := now.Add(idle)
+	if !startedAt.IsZero() {
+		if limit := startedAt.Add(t.options.Max); extended.After(limit) {
+			extended = limit
+		}
+	}
+	if !extended.After(expiresAt) {
+		return expiresAt, false
+	}
+
+	t.mu.Lock()
+	t.stats(clientType).extended++
+	t.mu.Unlock()
+	return extended, true
+}
+
+// ObserveStarted 记录申请的上传
+func (t *UploadTTLTuner) ObserveStarted(clientType string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stats(clientType).started++
+}
+
+// ObserveCompleted 记录合并完成的上传及其从申请到完成的耗时
+func (t *UploadTTLTuner) ObserveCompleted(clientType string, duration time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	stats := t.stats(clientType)
+	stats.completed++
+	if duration <= 0 {
+		return
+	}
+	if len(stats.durations) < uploadTTLSampleWindow {
+		stats.durations = append(stats.durations, duration)
+		return
+	}
+	stats.durations[stats.next] = duration
+	stats.next = (stats.next + 1) % uploadTTLSampleWindow
+}
+
+// ObserveAbandoned 记录过期被清理的上传
+func (t *UploadTTLTuner) ObserveAbandoned(clientType string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stats(clientType).abandoned++
+}
+
+// Metrics 返回各客户端的指标，按客户端类型排序
+func (t *UploadTTLTuner) Metrics() []UploadTTLMetrics {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	metrics := make([]UploadTTLMetrics, 0, len(t.clients))
+	for clientType, stats := range t.clients {
+		ttl, adaptive := t.ttl(stats)
+		m := UploadTTLMetrics{
+			ClientType:         clientType,
+			Started:            stats.started,
+			Completed:          stats.completed,
+			Abandoned:          stats.abandoned,
+			Extended:           stats.extended,
+			Samples:            len(stats.durations),
+			MedianDuration:     durationPercentile(stats.durations, 0.5).String(),
+			PercentileDuration: durationPercentile(stats.durations, t.options.Percentile).String(),
+			TTL:                ttl.String(),
+			Adaptive:           adaptive,
+		}
+		if finished := stats.completed + stats.abandoned; finished > 0 {
+			m.AbandonRate = float64(stats.abandoned) / float64(finished)
+		}
+		metrics = append(metrics, m)
+	}
+	sort.Slice(metrics, func(i, j int) bool { return metrics[i].ClientType < metrics[j].ClientType })
+	return metrics
+}
+
+// ttl 计算有效期并返回是否由观察数据得出，调用方需持有锁
+func (t *UploadTTLTuner) ttl(stats *uploadClientStats) (time.Duration, bool) {
+	if !t.options.Adaptive || len(stats.durations) < t.options.MinSamples {
+		return t.options.Default, false
+	}
+	observed := durationPercentile(stats.durations, t.options.Percentile)
+	ttl := time.Duration(float64(observed) * t.options.Multiplier).Round(time.Minute)
+	return min(max(ttl, t.options.Min), t.options.Max), true
+}
+
+// stats 返回客户端的统计，不存在时创建，调用方需持有锁
+func (t *UploadTTLTuner) stats(clientType string) *uploadClientStats {
+	clientType = NormalizeUploadClient(clientType)
+	stats, ok := t.clients[clientType]
+	if !ok {
+		stats = &uploadClientStats{}
+		t.clients[clientType] = stats
+	}
+	return stats
+}
+
+// durationPercentile 返回样本的分位数(最近秩法)，没有样本时返回0
+func durationPercentile(samples []time.Duration, percentile float64) time.Duration {
+	if len(samples) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	rank := int(math.Ceil(percentile*float64(len(sorted)))) - 1
+	return sorted[min(max(rank, 0), len(sorted)-1)]
+}
+
+// NormalizeUploadClient 规范化客户端类型，未知类型归为other，避免统计维度无限增长
+func NormalizeUploadClient(clientType string) string {
+	switch clientType = strings.ToLower(strings.TrimSpace(clientType)); clientType {
+	case UploadClientWeb, UploadClientDesktop, UploadClientMobile:
+		return clientType
+	default:
+		return UploadClientOther
+	}
+}
+
+// DetectUploadClient 返回上传客户端类型，客户端未声明时按User-Agent推断
+func DetectUploadClient(declared, userAgent string) string {
+	if strings.TrimSpace(declared) != "" {
+		return NormalizeUploadClient(declared)
+	}
+	ua := strings.ToLower(userAgent)
+	switch {
+	case ua == "":
+		return UploadClientOther
+	case strings.Contains(ua, "android"), strings.Contains(ua, "iphone"), strings.Contains(ua, "ipad"),
+		strings.Contains(ua, "mobile"), strings.Contains(ua, "okhttp"), strings.Contains(ua, "cfnetwork"):
+		return UploadClientMobile
+	case strings.Contains(ua, "electron"), strings.Contains(ua, "cloudpan-desktop"):
+		return UploadClientDesktop
+	case strings.HasPrefix(ua, "mozilla/"):
+		return UploadClientWeb
+	default:
+		return UploadClientOther
+	}
+}
+
+var (
+	defaultTTLTunerMu sync.RWMutex
+	defaultTTLTuner   *UploadTTLTuner
+)
+
+// SetDefaultUploadTTLTuner 设置全局上传有效期调整器，启动时由main调用，
+// 使接收上传和清理过期上传的服务共享统计
+func SetDefaultUploadTTLTuner(tuner *UploadTTLTuner) {
+	defaultTTLTunerMu.Lock()
+	defer defaultTTLTunerMu.Unlock()
+	defaultTTLTuner = tuner
+}
+
+// DefaultUploadTTLTuner 返回全局上传有效期调整器，未创建时返回nil
+func DefaultUploadTTLTuner() *UploadTTLTuner {
+	defaultTTLTunerMu.RLock()
+	defer defaultTTLTunerMu.RUnlock()
+	return defaultTTLTuner
+}
@@ -0,0 +1,108 @@
+package file
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUploadTTLTuner_TTL(t *testing.T) {
+	tuner := NewUploadTTLTuner(UploadTTLOptions{
+		Default: 24 * time.Hour, Min: 2 * time.Hour, Max: 72 * time.Hour,
+		Adaptive: true, Percentile: 0.9, Multiplier: 2, MinSamples: 3,
+	})
+
+	// 样本不足时使用固定有效期
+	tuner.ObserveCompleted(UploadClientMobile, 20*time.Hour)
+	tuner.ObserveCompleted(UploadClientMobile, 30*time.Hour)
+	assert.Equal(t, 24*time.Hour, tuner.TTL(UploadClientMobile))
+
+	// 慢速客户端按分位数耗时延长，不超过上限
+	tuner.ObserveCompleted(UploadClientMobile, 25*time.Hour)
+	assert.Equal(t, 60*time.Hour, tuner.TTL(UploadClientMobile))
+	tuner.ObserveCompleted(UploadClientMobile, 50*time.Hour)
+	assert.Equal(t, 72*time.Hour, tuner.TTL(UploadClientMobile))
+
+	// 快速客户端缩短，不低于下限
+	for i := 0; i < 3; i++ {
+		tuner.ObserveCompleted(UploadClientDesktop, 10*time.Minute)
+	}
+	assert.Equal(t, 2*time.Hour, tuner.TTL(UploadClientDesktop))
+
+	// 未启用自适应时始终使用固定有效期
+	fixed := NewUploadTTLTuner(UploadTTLOptions{MinSamples: 1})
+	fixed.ObserveCompleted(UploadClientWeb, time.Minute)
+	assert.Equal(t, DefaultChunkUploadTTL, fixed.TTL(UploadClientWeb))
+	assert.Equal(t, time.Hour, fixed.MinTTL())
+}
+
+func TestUploadTTLTuner_Extend(t *testing.T) {
+	tuner := NewUploadTTLTuner(UploadTTLOptions{Default: 24 * time.Hour, Max: 48 * time.Hour, IdleTimeout: 6 * time.Hour})
+	started := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	expires := started.Add(24 * time.Hour)
+
+	// 剩余有效期充足时不延长
+	_, ok := tuner.Extend(UploadClientMobile, started, expires, started.Add(20*time.Hour))
+	assert.False(t, ok)
+
+	extended, ok := tuner.Extend(UploadClientMobile, started, expires, started.Add(22*time.Hour))
+	require.True(t, ok)
+	assert.Equal(t, started.Add(28*time.Hour), extended)
+
+	// 不超过申请时间之后的上限
+	extended, ok = tuner.Extend(UploadClientMobile, started, started.Add(46*time.Hour), started.Add(45*time.Hour))
+	require.True(t, ok)
+	assert.Equal(t, started.Add(48*time.Hour), extended)
+	_, ok = tuner.Extend(UploadClientMobile, started, started.Add(48*time.Hour), started.Add(47*time.Hour))
+	assert.False(t, ok)
+
+	metrics := tuner.Metrics()
+	require.Len(t, metrics, 1)
+	assert.Equal(t, int64(2), metrics[0].Extended)
+
+	disabled := NewUploadTTLTuner(UploadTTLOptions{})
+	_, ok = disabled.Extend(UploadClientMobile, started, expires, expires.Add(-time.Minute))
+	assert.False(t, ok)
+}
+
+func TestUploadTTLTuner_Metrics(t *testing.T) {
+	tuner := NewUploadTTLTuner(UploadTTLOptions{Percentile: 0.5})
+	tuner.ObserveStarted("Android")
+	tuner.ObserveStarted(UploadClientWeb)
+	tuner.ObserveCompleted(UploadClientWeb, time.Hour)
+	tuner.ObserveCompleted(UploadClientWeb, 3*time.Hour)
+	tuner.ObserveAbandoned(UploadClientWeb)
+	tuner.ObserveAbandoned(UploadClientWeb)
+
+	metrics := tuner.Metrics()
+	require.Len(t, metrics, 2)
+	assert.Equal(t, UploadClientOther, metrics[0].ClientType, "未知客户端归为other")
+	web := metrics[1]
+	assert.Equal(t, int64(2), web.Completed)
+	assert.Equal(t, 0.5, web.AbandonRate)
+	assert.Equal(t, "1h0m0s", web.MedianDuration)
+	assert.Equal(t, DefaultChunkUploadTTL.String(), web.TTL)
+	assert.False(t, web.Adaptive)
+}
+
+func TestDetectUploadClient(t *testing.T) {
+	tests := []struct {
+		declared  string
+		userAgent string
+		want      string
+	}{
+		{declared: "Mobile", want: UploadClientMobile},
+		{declared: "tv", want: UploadClientOther},
+		{userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X)", want: UploadClientMobile},
+		{userAgent: "okhttp/4.12.0", want: UploadClientMobile},
+		{userAgent: "Mozilla/5.0 (Windows NT 10.0) CloudPan/1.2.0 Electron/28.0.0", want: UploadClientDesktop},
+		{userAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_0) Safari/605.1.15", want: UploadClientWeb},
+		{userAgent: "curl/8.4.0", want: UploadClientOther},
+		{want: UploadClientOther},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, DetectUploadClient(tt.declared, tt.userAgent), "%q %q", tt.declared, tt.userAgent)
+	}
+}
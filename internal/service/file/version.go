@@ -0,0 +1,535 @@
+package file
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/cache"
+	"cloudpan/internal/pkg/config"
+	basemodels "cloudpan/internal/pkg/database/models"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/storage"
+	filerepo "cloudpan/internal/repository/file"
+	"cloudpan/internal/repository/models"
+)
+
+// 文件历史版本默认值
+const (
+	DefaultMaxVersions      = 10      // 每个文件保留的历史版本数
+	DefaultMaxOverwriteSize = 1 << 30 // 单次请求覆盖上传的最大文件大小
+	maxChangeLogLength      = 500     // 变更说明的最大字符数
+)
+
+// VersionService 文件历史版本服务接口
+//
+// 文件内容被替换时原来的内容保留为历史版本，版本号按文件递增：
+// 1. 覆盖上传：写入新内容并把原内容登记为历史版本，新内容占用存储空间，空间不足时拒绝
+// 2. 查看和下载：列出文件的历史版本，按版本号下载历史内容
+// 3. 恢复：当前内容登记为新的历史版本，被恢复版本的内容成为当前内容，该版本记录随之删除，不额外占用存储空间
+// 4. 保留上限：每个文件最多保留 MaxVersions 个历史版本，超出的最早版本连同存储对象删除并释放存储空间
+// 5. 彻底删除：回收站彻底删除文件时由 PurgeVersions 删除其全部历史版本并返回释放的空间
+//
+// 替换内容后文件的病毒扫描状态重置为待扫描、预览需要重新生成，由调用方安排扫描和预览任务；
+// 同时使父链上的文件夹校验和以及文件的信息、预览和下载缓存失效
+//
+// 使用示例：
+//
+//	service := NewVersionService(fileRepo, versionRepo, userRepo, store, fileService, cacheManager, VersionOptionsFromConfig(cfg.Storage.Versions), logger)
+//	file, err := service.Overwrite(ctx, userID, fileID, &OverwriteRequest{Content: body, Size: size})
+//	versions, err := service.ListVersions(ctx, userID, fileID)
+//	file, err = service.RestoreVersion(ctx, userID, fileID, versions[0].VersionNumber)
+type VersionService interface {
+	Overwrite(ctx context.Context, userID, fileID uint, req *OverwriteRequest) (*models.File, error)
+	ListVersions(ctx context.Context, userID, fileID uint) ([]*FileVersion, error)
+	OpenVersion(ctx context.Context, userID, fileID uint, number int) (*FileDownload, error)
+	RestoreVersion(ctx context.Context, userID, fileID uint, number int) (*models.File, error)
+	PurgeVersions(ctx context.Context, fileIDs []uint) (int64, error)
+}
+
+// VersionOptions 文件历史版本选项
+type VersionOptions struct {
+	MaxVersions      int    // 每个文件保留的历史版本数
+	MaxOverwriteSize int64  // 单次请求覆盖上传的最大文件大小
+	KeyPrefix        string // 覆盖上传的存储对象路径前缀
+}
+
+// VersionOptionsFromConfig 从历史版本配置生成选项
+func VersionOptionsFromConfig(cfg config.VersionsConfig) VersionOptions {
+	return VersionOptions{
+		MaxVersions:      cfg.MaxVersions,
+		MaxOverwriteSize: cfg.MaxOverwriteSize,
+	}
+}
+
+// OverwriteRequest 覆盖上传请求
+type OverwriteRequest struct {
+	Content   io.Reader // 新内容
+	Size      int64     // 声明的内容大小，与实际写入的大小必须一致
+	MimeType  string    // 新内容的类型，为空时保持不变
+	ChangeLog string    // 原内容的变更说明，记录在登记的历史版本上
+}
+
+// FileVersion 文件的历史版本
+type FileVersion struct {
+	VersionNumber int       `json:"version_number"`       // 版本号
+	Name          string    `json:"name"`                 // 登记版本时的文件名
+	Size          int64     `json:"size"`                 // 内容大小
+	Hash          string    `json:"hash,omitempty"`       // 内容哈希
+	MimeType      *string   `json:"mime_type,omitempty"`  // 内容类型
+	ChangeLog     *string   `json:"change_log,omitempty"` // 变更说明
+	CreatedBy     uint      `json:"created_by"`           // 登记版本的用户ID
+	CreatedAt     time.Time `json:"created_at"`           // 登记时间
+}
+
+// versionService 文件历史版本服务实现
+type versionService struct {
+	fileRepo    filerepo.FileRepository
+	versionRepo filerepo.VersionRepository
+	accounts    StorageAccountStore
+	store       storage.Storage
+	checksums   ChecksumInvalidator
+	cache       CacheDeleter
+	keys        *cache.KeyBuilder
+	options     VersionOptions
+	logger      *zap.Logger
+	now         func() time.Time
+}
+
+// NewVersionService 创建文件历史版本服务实例
+//
+// checksums 和 cacheDeleter 可以为nil(如Redis未初始化)，此时跳过对应的失效处理
+func NewVersionService(fileRepo filerepo.FileRepository, versionRepo filerepo.VersionRepository, accounts StorageAccountStore,
+	store storage.Storage, checksums ChecksumInvalidator, cacheDeleter CacheDeleter, options VersionOptions, logger *zap.Logger) VersionService {
+	if options.MaxVersions <= 0 {
+		options.MaxVersions = DefaultMaxVersions
+	}
+	if options.MaxOverwriteSize <= 0 {
+		options.MaxOverwriteSize = DefaultMaxOverwriteSize
+	}
+	options.KeyPrefix = strings.Trim(options.KeyPrefix, "/")
+	if options.KeyPrefix == "" {
+		options.KeyPrefix = "files"
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &versionService{
+		fileRepo:    fileRepo,
+		versionRepo: versionRepo,
+		accounts:    accounts,
+		store:       store,
+		checksums:   checksums,
+		cache:       cacheDeleter,
+		keys:        cache.NewKeyBuilder(),
+		options:     options,
+		logger:      logger,
+		now:         time.Now,
+	}
+}
+
+// Overwrite 用新内容覆盖文件，原内容登记为历史版本
+//
+// 新内容先写入新的存储对象，大小不一致或登记失败时删除该对象，文件保持不变
+func (s *versionService) Overwrite(ctx context.Context, userID, fileID uint, req *OverwriteRequest) (*models.File, error) {
+	if req == nil || req.Content == nil {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "文件内容不能为空")
+	}
+	if req.Size < 0 || req.Size > s.options.MaxOverwriteSize {
+		return nil, pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "文件大小必须在0到 %d 字节之间", s.options.MaxOverwriteSize)
+	}
+	changeLog := strings.TrimSpace(req.ChangeLog)
+	if utf8.RuneCountInString(changeLog) > maxChangeLogLength {
+		return nil, pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "变更说明不能超过%d个字符", maxChangeLogLength)
+	}
+
+	file, err := s.getVersionable(ctx, userID, fileID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkQuota(ctx, userID, req.Size); err != nil {
+		return nil, err
+	}
+
+	key := path.Join(s.options.KeyPrefix, strconv.FormatUint(uint64(userID), 10), s.now().UTC().Format("2006/01"), basemodels.GenerateUUID())
+	hash, err := s.writeContent(ctx, key, req.Content, req.Size)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := newVersionSnapshot(file, userID)
+	if changeLog != "" {
+		snapshot.ChangeLog = &changeLog
+	}
+	oldPath := *file.StoragePath
+	hashType := "sha256"
+	file.Size = req.Size
+	file.Hash = &hash
+	file.HashType = &hashType
+	file.StoragePath = &key
+	if mimeType := strings.TrimSpace(req.MimeType); mimeType != "" {
+		file.MimeType = &mimeType
+	}
+	if err := s.replace(ctx, file, oldPath, snapshot, nil); err != nil {
+		s.deleteObject(ctx, key)
+		return nil, err
+	}
+
+	if req.Size > 0 {
+		if err := s.accounts.UpdateStorageUsed(ctx, userID, req.Size); err != nil {
+			s.logger.Error("Failed to update storage usage after overwrite",
+				zap.Uint("user_id", userID),
+				zap.Uint("file_id", file.ID),
+				zap.Int64("size", req.Size),
+				zap.Error(err))
+		}
+	}
+	s.prune(ctx, file)
+
+	s.logger.Info("File overwritten",
+		zap.Uint("user_id", userID),
+		zap.Uint("file_id", file.ID),
+		zap.Int("version", snapshot.VersionNumber),
+		zap.Int64("size", req.Size))
+	return file, nil
+}
+
+// ListVersions 列出文件的历史版本，按版本号降序排列
+func (s *versionService) ListVersions(ctx context.Context, userID, fileID uint) ([]*FileVersion, error) {
+	if _, err := s.getVersionable(ctx, userID, fileID); err != nil {
+		return nil, err
+	}
+	versions, err := s.versionRepo.ListByFile(ctx, fileID)
+	if err != nil {
+		return nil, fmt.Errorf("获取历史版本失败: %w", err)
+	}
+	views := make([]*FileVersion, len(versions))
+	for i, version := range versions {
+		views[i] = newFileVersionView(version)
+	}
+	return views, nil
+}
+
+// OpenVersion 打开历史版本的内容，文件名和修改时间取登记版本时的值
+func (s *versionService) OpenVersion(ctx context.Context, userID, fileID uint, number int) (*FileDownload, error) {
+	file, err := s.getVersionable(ctx, userID, fileID)
+	if err != nil {
+		return nil, err
+	}
+	version, err := s.getVersion(ctx, file.ID, number)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := s.store.Stat(ctx, version.StoragePath)
+	if err != nil {
+		if storage.IsNotFound(err) {
+			s.logger.Error("File version content missing from storage",
+				zap.Uint("file_id", file.ID),
+				zap.Int("version", number),
+				zap.String("path", version.StoragePath))
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "版本内容不存在")
+		}
+		return nil, fmt.Errorf("读取版本信息失败: %w", err)
+	}
+
+	download := &FileDownload{
+		FileID:   file.ID,
+		Name:     version.Name,
+		MimeType: "application/octet-stream",
+		Size:     info.Size,
+		ModTime:  version.CreatedAt,
+		Content:  storage.OpenSeekable(ctx, s.store, version.StoragePath, info.Size),
+	}
+	if version.MimeType != nil && *version.MimeType != "" {
+		download.MimeType = *version.MimeType
+	}
+	if version.Hash != "" {
+		download.ETag = `"` + version.Hash + `"`
+	}
+	return download, nil
+}
+
+// RestoreVersion 将历史版本恢复为当前内容
+//
+// 当前内容登记为新的历史版本，被恢复版本的存储对象直接成为当前内容，不复制数据，存储用量不变；
+// 文件名保持不变
+func (s *versionService) RestoreVersion(ctx context.Context, userID, fileID uint, number int) (*models.File, error) {
+	file, err := s.getVersionable(ctx, userID, fileID)
+	if err != nil {
+		return nil, err
+	}
+	version, err := s.getVersion(ctx, file.ID, number)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := newVersionSnapshot(file, userID)
+	changeLog := fmt.Sprintf("恢复版本 %d 前的内容", number)
+	snapshot.ChangeLog = &changeLog
+	oldPath := *file.StoragePath
+	hashType := "sha256"
+	storagePath := version.StoragePath
+	file.Size = version.Size
+	file.Hash = nil
+	file.HashType = nil
+	if version.Hash != "" {
+		hash := version.Hash
+		file.Hash = &hash
+		if len(hash) != sha256.Size*2 {
+			hashType = "md5"
+		}
+		file.HashType = &hashType
+	}
+	file.StoragePath = &storagePath
+	file.MimeType = version.MimeType
+	if err := s.replace(ctx, file, oldPath, snapshot, version); err != nil {
+		return nil, err
+	}
+	s.prune(ctx, file)
+
+	s.logger.Info("File version restored",
+		zap.Uint("user_id", userID),
+		zap.Uint("file_id", file.ID),
+		zap.Int("restored", number),
+		zap.Int("version", snapshot.VersionNumber))
+	return file, nil
+}
+
+// PurgeVersions 删除一组文件的全部历史版本及其存储对象，返回释放的存储空间
+//
+// 由回收站彻底删除文件时调用，存储对象删除失败时返回错误，已删除的对象在重试时忽略
+func (s *versionService) PurgeVersions(ctx context.Context, fileIDs []uint) (int64, error) {
+	versions, err := s.versionRepo.ListByFiles(ctx, fileIDs)
+	if err != nil {
+		return 0, fmt.Errorf("获取历史版本失败: %w", err)
+	}
+	if len(versions) == 0 {
+		return 0, nil
+	}
+
+	ids := make([]uint, len(versions))
+	var reclaimed int64
+	for i, version := range versions {
+		if err := s.store.Delete(ctx, version.StoragePath); err != nil {
+			return 0, fmt.Errorf("删除版本存储对象失败: %w", err)
+		}
+		ids[i] = version.ID
+		reclaimed += version.Size
+	}
+	if err := s.versionRepo.Delete(ctx, ids); err != nil {
+		return 0, fmt.Errorf("删除历史版本失败: %w", err)
+	}
+	return reclaimed, nil
+}
+
+// replace 登记历史版本并写入文件的新内容，成功后使缓存和校验和失效
+func (s *versionService) replace(ctx context.Context, file *models.File, oldPath string, snapshot, restored *models.FileVersion) error {
+	file.ScanStatus = models.ScanStatusPending
+	file.PreviewStatus = ""
+	file.ThumbnailURL = nil
+	file.PreviewURL = nil
+	if err := s.versionRepo.ReplaceContent(ctx, file, oldPath, snapshot, restored); err != nil {
+		if errors.Is(err, filerepo.ErrContentChanged) {
+			return pkgErrors.WrapError(pkgErrors.ErrResourceExists, "文件内容已被修改，请刷新后重试")
+		}
+		return fmt.Errorf("保存文件版本失败: %w", err)
+	}
+	file.UpdatedAt = s.now()
+	file.Version++
+
+	if s.checksums != nil {
+		if err := s.checksums.InvalidateChecksums(ctx, file.ID); err != nil {
+			s.logger.Warn("Failed to invalidate folder checksums",
+				zap.Uint("file_id", file.ID),
+				zap.Error(err))
+		}
+	}
+	if s.cache != nil {
+		id := strconv.FormatUint(uint64(file.ID), 10)
+		if err := s.cache.Delete(s.keys.FileInfo(id), s.keys.FilePreview(id), s.keys.FileDownload(id)); err != nil {
+			s.logger.Warn("Failed to evict file cache",
+				zap.Uint("file_id", file.ID),
+				zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// prune 删除超出保留个数的最早版本并释放存储空间，失败只记录日志，下次替换内容时重试
+func (s *versionService) prune(ctx context.Context, file *models.File) {
+	versions, err := s.versionRepo.ListByFile(ctx, file.ID)
+	if err != nil {
+		s.logger.Warn("Failed to list file versions for pruning", zap.Uint("file_id", file.ID), zap.Error(err))
+		return
+	}
+	if len(versions) <= s.options.MaxVersions {
+		return
+	}
+
+	var ids []uint
+	var reclaimed int64
+	for _, version := range versions[s.options.MaxVersions:] {
+		if err := s.store.Delete(ctx, version.StoragePath); err != nil {
+			s.logger.Warn("Failed to delete pruned version content",
+				zap.Uint("file_id", file.ID),
+				zap.Int("version", version.VersionNumber),
+				zap.Error(err))
+			continue
+		}
+		ids = append(ids, version.ID)
+		reclaimed += version.Size
+	}
+	if len(ids) == 0 {
+		return
+	}
+	if err := s.versionRepo.Delete(ctx, ids); err != nil {
+		// 存储对象已删除，记录在下次清理时再次删除
+		s.logger.Warn("Failed to delete pruned versions", zap.Uint("file_id", file.ID), zap.Error(err))
+		return
+	}
+	if reclaimed > 0 {
+		if err := s.accounts.UpdateStorageUsed(ctx, file.UserID, -reclaimed); err != nil {
+			s.logger.Error("Failed to release storage quota after pruning versions",
+				zap.Uint("user_id", file.UserID),
+				zap.Int64("size", reclaimed),
+				zap.Error(err))
+		}
+	}
+	s.logger.Info("File versions pruned",
+		zap.Uint("file_id", file.ID),
+		zap.Int("versions", len(ids)),
+		zap.Int64("reclaimed", reclaimed))
+}
+
+// writeContent 将内容写入存储对象并计算SHA-256，实际大小与声明不一致时放弃写入
+func (s *versionService) writeContent(ctx context.Context, key string, content io.Reader, size int64) (string, error) {
+	writer, err := s.store.Create(ctx, key)
+	if err != nil {
+		return "", fmt.Errorf("创建存储对象失败: %w", err)
+	}
+
+	hasher := sha256.New()
+	// 多读一个字节用于发现超出声明大小的请求体
+	written, err := io.Copy(io.MultiWriter(writer, hasher), io.LimitReader(content, size+1))
+	if err == nil && written != size {
+		err = pkgErrors.WrapErrorf(pkgErrors.ErrValidationFailed, "文件大小不一致: 声明 %d，实际 %d", size, written)
+	}
+	if err != nil {
+		_ = writer.Abort()
+		return "", err
+	}
+	if err := writer.Close(); err != nil {
+		_ = writer.Abort()
+		return "", fmt.Errorf("保存文件内容失败: %w", err)
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// getVersionable 获取属于用户、可以管理历史版本的文件
+func (s *versionService) getVersionable(ctx context.Context, userID, fileID uint) (*models.File, error) {
+	if userID == 0 || fileID == 0 {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "用户ID和文件ID不能为空")
+	}
+	file, err := s.fileRepo.GetByID(ctx, fileID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "文件不存在")
+		}
+		return nil, fmt.Errorf("获取文件失败: %w", err)
+	}
+	if file.UserID != userID {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, "无权访问该文件")
+	}
+	if !file.IsActive() {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "文件当前不可操作")
+	}
+	if file.IsFolder || file.StoragePath == nil {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "文件夹没有历史版本")
+	}
+	if file.ArchiveState(s.now()) != models.ArchiveStateAvailable {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "文件已归档，请先恢复后再操作")
+	}
+	if file.StorageType != s.store.Type() {
+		return nil, pkgErrors.WrapErrorf(pkgErrors.ErrOperationNotAllowed, "文件所在的存储(%s)当前不可用", file.StorageType)
+	}
+	return file, nil
+}
+
+// getVersion 按版本号获取文件的历史版本
+func (s *versionService) getVersion(ctx context.Context, fileID uint, number int) (*models.FileVersion, error) {
+	if number <= 0 {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "版本号必须大于0")
+	}
+	version, err := s.versionRepo.GetByNumber(ctx, fileID, number)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "版本不存在")
+		}
+		return nil, fmt.Errorf("获取历史版本失败: %w", err)
+	}
+	return version, nil
+}
+
+// checkQuota 检查用户剩余存储空间
+func (s *versionService) checkQuota(ctx context.Context, userID uint, size int64) error {
+	if size <= 0 {
+		return nil
+	}
+	user, err := s.accounts.GetByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("获取用户失败: %w", err)
+	}
+	if user.StorageQuota > 0 && !user.HasStorageSpace(size) {
+		return pkgErrors.WrapError(pkgErrors.ErrQuotaExceeded, "存储空间不足")
+	}
+	return nil
+}
+
+// deleteObject 删除未登记成功的存储对象，失败只记录日志
+func (s *versionService) deleteObject(ctx context.Context, key string) {
+	if err := s.store.Delete(ctx, key); err != nil {
+		s.logger.Warn("Failed to delete unused object", zap.String("path", key), zap.Error(err))
+	}
+}
+
+// newVersionSnapshot 以文件的当前内容创建历史版本记录，版本号由仓储分配
+func newVersionSnapshot(file *models.File, userID uint) *models.FileVersion {
+	snapshot := &models.FileVersion{
+		FileID:      file.ID,
+		Name:        file.Name,
+		Size:        file.Size,
+		StoragePath: *file.StoragePath,
+		MimeType:    file.MimeType,
+		CreatedBy:   userID,
+	}
+	if file.Hash != nil {
+		snapshot.Hash = *file.Hash
+	}
+	return snapshot
+}
+
+// newFileVersionView 转换为接口返回的历史版本
+func newFileVersionView(version *models.FileVersion) *FileVersion {
+	return &FileVersion{
+		VersionNumber: version.VersionNumber,
+		Name:          version.Name,
+		Size:          version.Size,
+		Hash:          version.Hash,
+		MimeType:      version.MimeType,
+		ChangeLog:     version.ChangeLog,
+		CreatedBy:     version.CreatedBy,
+		CreatedAt:     version.CreatedAt,
+	}
+}
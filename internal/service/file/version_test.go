@@ -0,0 +1,265 @@
+package file
+
+import (
+	"context"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/storage"
+	filerepo "cloudpan/internal/repository/file"
+	"cloudpan/internal/repository/models"
+)
+
+// memoryVersions 内存文件历史版本仓储
+type memoryVersions struct {
+	versions []*models.FileVersion
+	nextID   uint
+	conflict bool // 模拟文件内容已被并发替换
+}
+
+func (m *memoryVersions) ListByFile(_ context.Context, fileID uint) ([]*models.FileVersion, error) {
+	var versions []*models.FileVersion
+	for _, version := range m.versions {
+		if version.FileID == fileID {
+			versions = append(versions, version)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].VersionNumber > versions[j].VersionNumber })
+	return versions, nil
+}
+
+func (m *memoryVersions) GetByNumber(_ context.Context, fileID uint, number int) (*models.FileVersion, error) {
+	for _, version := range m.versions {
+		if version.FileID == fileID && version.VersionNumber == number {
+			return version, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *memoryVersions) ListByFiles(_ context.Context, fileIDs []uint) ([]*models.FileVersion, error) {
+	var versions []*models.FileVersion
+	for _, version := range m.versions {
+		for _, id := range fileIDs {
+			if version.FileID == id {
+				versions = append(versions, version)
+			}
+		}
+	}
+	return versions, nil
+}
+
+func (m *memoryVersions) ReplaceContent(_ context.Context, file *models.File, _ string, snapshot, restored *models.FileVersion) error {
+	if m.conflict {
+		return filerepo.ErrContentChanged
+	}
+	latest := 0
+	for _, version := range m.versions {
+		if version.FileID == file.ID && version.VersionNumber > latest {
+			latest = version.VersionNumber
+		}
+	}
+	m.nextID++
+	snapshot.ID = m.nextID
+	snapshot.FileID = file.ID
+	snapshot.VersionNumber = latest + 1
+	snapshot.CreatedAt = time.Now()
+	m.versions = append(m.versions, snapshot)
+	if restored != nil {
+		return m.Delete(context.Background(), []uint{restored.ID})
+	}
+	return nil
+}
+
+func (m *memoryVersions) Delete(_ context.Context, ids []uint) error {
+	kept := m.versions[:0]
+	for _, version := range m.versions {
+		deleted := false
+		for _, id := range ids {
+			deleted = deleted || version.ID == id
+		}
+		if !deleted {
+			kept = append(kept, version)
+		}
+	}
+	m.versions = kept
+	return nil
+}
+
+type versionFixture struct {
+	service  VersionService
+	files    *memoryFileRepository
+	versions *memoryVersions
+	accounts *MockStorageAccountStore
+	store    storage.Storage
+}
+
+func newVersionFixture(t *testing.T, maxVersions int) *versionFixture {
+	t.Helper()
+	store, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, store.Put(context.Background(), "files/7/v0", strings.NewReader("v0"), 2))
+
+	storagePath := "files/7/v0"
+	mimeType := "text/plain"
+	files := &memoryFileRepository{files: map[uint]*models.File{
+		1: {Name: "notes.txt", UserID: 7, Status: "active", Size: 2, StorageType: store.Type(), StoragePath: &storagePath, MimeType: &mimeType},
+		2: {Name: "docs", UserID: 7, Status: "active", IsFolder: true},
+	}}
+	for id, f := range files.files {
+		f.ID = id
+	}
+
+	fixture := &versionFixture{
+		files:    files,
+		versions: &memoryVersions{},
+		accounts: new(MockStorageAccountStore),
+		store:    store,
+	}
+	fixture.service = NewVersionService(files, fixture.versions, fixture.accounts, store, nil, nil,
+		VersionOptions{MaxVersions: maxVersions, MaxOverwriteSize: 16}, zap.NewNop())
+	return fixture
+}
+
+func (f *versionFixture) overwrite(t *testing.T, content string) *models.File {
+	t.Helper()
+	file, err := f.service.Overwrite(context.Background(), 7, 1, &OverwriteRequest{
+		Content: strings.NewReader(content),
+		Size:    int64(len(content)),
+	})
+	require.NoError(t, err)
+	return file
+}
+
+func (f *versionFixture) read(t *testing.T, number int) string {
+	t.Helper()
+	download, err := f.service.OpenVersion(context.Background(), 7, 1, number)
+	require.NoError(t, err)
+	defer download.Content.Close()
+	data, err := io.ReadAll(download.Content)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestVersionService_OverwriteAndRestore(t *testing.T) {
+	f := newVersionFixture(t, 10)
+	ctx := context.Background()
+	f.accounts.On("GetByID", mock.Anything, uint(7)).Return(&models.User{StorageQuota: 1024, StorageUsed: 2}, nil)
+	f.accounts.On("UpdateStorageUsed", mock.Anything, uint(7), int64(3)).Return(nil)
+
+	file, err := f.service.Overwrite(ctx, 7, 1, &OverwriteRequest{
+		Content:   strings.NewReader("v1!"),
+		Size:      3,
+		ChangeLog: " 初稿 ",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), file.Size)
+	assert.Equal(t, models.ScanStatusPending, file.ScanStatus)
+	assert.NotEqual(t, "files/7/v0", *file.StoragePath)
+	require.NotNil(t, file.Hash)
+	assert.Len(t, *file.Hash, 64)
+
+	versions, err := f.service.ListVersions(ctx, 7, 1)
+	require.NoError(t, err)
+	require.Len(t, versions, 1)
+	assert.Equal(t, 1, versions[0].VersionNumber)
+	assert.Equal(t, int64(2), versions[0].Size)
+	require.NotNil(t, versions[0].ChangeLog)
+	assert.Equal(t, "初稿", *versions[0].ChangeLog)
+	assert.Equal(t, "v0", f.read(t, 1))
+
+	// 恢复时当前内容成为新版本，被恢复的版本移出历史，不改变存储用量
+	current := *file.StoragePath
+	restored, err := f.service.RestoreVersion(ctx, 7, 1, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "files/7/v0", *restored.StoragePath)
+	assert.Equal(t, int64(2), restored.Size)
+
+	versions, err = f.service.ListVersions(ctx, 7, 1)
+	require.NoError(t, err)
+	require.Len(t, versions, 1)
+	assert.Equal(t, 2, versions[0].VersionNumber)
+	assert.Equal(t, "v1!", f.read(t, 2))
+	assert.Equal(t, current, f.versions.versions[0].StoragePath)
+
+	_, err = f.service.OpenVersion(ctx, 7, 1, 1)
+	assert.ErrorIs(t, err, pkgErrors.ErrResourceNotFound)
+	f.accounts.AssertNumberOfCalls(t, "UpdateStorageUsed", 1)
+}
+
+func TestVersionService_PrunesOldestVersions(t *testing.T) {
+	f := newVersionFixture(t, 2)
+	f.accounts.On("GetByID", mock.Anything, uint(7)).Return(&models.User{}, nil)
+	f.accounts.On("UpdateStorageUsed", mock.Anything, uint(7), mock.Anything).Return(nil)
+
+	f.overwrite(t, "v1")
+	f.overwrite(t, "v2")
+	f.overwrite(t, "v3")
+
+	versions, err := f.service.ListVersions(context.Background(), 7, 1)
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	assert.Equal(t, 3, versions[0].VersionNumber)
+	assert.Equal(t, 2, versions[1].VersionNumber)
+
+	// 最早版本的存储对象被删除并释放其占用的空间
+	exists, err := f.store.Exists(context.Background(), "files/7/v0")
+	require.NoError(t, err)
+	assert.False(t, exists)
+	f.accounts.AssertCalled(t, "UpdateStorageUsed", mock.Anything, uint(7), int64(-2))
+}
+
+func TestVersionService_OverwriteRejected(t *testing.T) {
+	f := newVersionFixture(t, 10)
+	ctx := context.Background()
+	f.accounts.On("GetByID", mock.Anything, uint(7)).Return(&models.User{StorageQuota: 10, StorageUsed: 9}, nil)
+
+	// 存储空间不足
+	_, err := f.service.Overwrite(ctx, 7, 1, &OverwriteRequest{Content: strings.NewReader("abc"), Size: 3})
+	assert.ErrorIs(t, err, pkgErrors.ErrQuotaExceeded)
+
+	// 实际大小与声明不一致时不登记版本
+	_, err = f.service.Overwrite(ctx, 7, 1, &OverwriteRequest{Content: strings.NewReader("abc"), Size: 1})
+	assert.ErrorIs(t, err, pkgErrors.ErrValidationFailed)
+	assert.Empty(t, f.versions.versions)
+
+	_, err = f.service.Overwrite(ctx, 7, 1, &OverwriteRequest{Content: strings.NewReader(""), Size: 17})
+	assert.ErrorIs(t, err, pkgErrors.ErrInvalidInput)
+	_, err = f.service.Overwrite(ctx, 7, 2, &OverwriteRequest{Content: strings.NewReader(""), Size: 0})
+	assert.ErrorIs(t, err, pkgErrors.ErrOperationNotAllowed)
+	_, err = f.service.Overwrite(ctx, 8, 1, &OverwriteRequest{Content: strings.NewReader(""), Size: 0})
+	assert.ErrorIs(t, err, pkgErrors.ErrPermissionDenied)
+
+	// 文件内容被并发替换
+	f.versions.conflict = true
+	_, err = f.service.Overwrite(ctx, 7, 1, &OverwriteRequest{Content: strings.NewReader(""), Size: 0})
+	assert.ErrorIs(t, err, pkgErrors.ErrResourceExists)
+	f.accounts.AssertNotCalled(t, "UpdateStorageUsed", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestVersionService_PurgeVersions(t *testing.T) {
+	f := newVersionFixture(t, 10)
+	f.accounts.On("GetByID", mock.Anything, uint(7)).Return(&models.User{}, nil)
+	f.accounts.On("UpdateStorageUsed", mock.Anything, uint(7), mock.Anything).Return(nil)
+	f.overwrite(t, "v1")
+	f.overwrite(t, "v22")
+
+	reclaimed, err := f.service.PurgeVersions(context.Background(), []uint{1})
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), reclaimed)
+	assert.Empty(t, f.versions.versions)
+
+	reclaimed, err = f.service.PurgeVersions(context.Background(), []uint{1})
+	require.NoError(t, err)
+	assert.Zero(t, reclaimed)
+}
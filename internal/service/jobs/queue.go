@@ -0,0 +1,382 @@
+// Package jobs 进程内后台任务队列
+//
+// 缩略图生成、视频转码等媒体处理任务按类型进入独立的工作池，每个工作池限制同时执行的任务数，
+// 避免媒体处理占满CPU和IO导致API请求变慢。用户正在等待的预览任务优先于批量回填任务执行，
+// 批量任务最多占用部分并发数，为预览任务保留空位。队列满时拒绝新任务，由调用方稍后重试或降级
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/config"
+)
+
+// 任务队列相关错误
+var (
+	// ErrQueueFull 工作池排队任务已达上限
+	ErrQueueFull = errors.New("job queue full")
+	// ErrQueueClosed 任务队列已关闭
+	ErrQueueClosed = errors.New("job queue closed")
+)
+
+// 任务类型
+const (
+	TypeThumbnail  = "thumbnail"  // 图片缩略图生成
+	TypeTranscode  = "transcode"  // 视频转码
+	TypeAutomation = "automation" // 文件夹自动化规则执行
+	TypeScan       = "scan"       // 上传文件病毒扫描
+)
+
+// 配置缺失时使用的内置默认值
+const (
+	defaultConcurrency = 1
+	defaultQueueSize   = 256
+)
+
+// Priority 任务优先级
+type Priority int
+
+const (
+	// PriorityBatch 批量回填任务，如为历史文件补生成缩略图
+	PriorityBatch Priority = iota
+	// PriorityInteractive 用户正在等待结果的任务，如刚上传文件的预览
+	PriorityInteractive
+)
+
+// String 优先级名称，用于日志
+func (p Priority) String() string {
+	if p == PriorityInteractive {
+		return "interactive"
+	}
+	return "batch"
+}
+
+// Job 后台任务
+type Job struct {
+	Type     string                          // 任务类型，决定进入哪个工作池
+	Priority Priority                        // 优先级
+	Key      string                          // 任务标识，如文件ID，用于日志
+	Run      func(ctx context.Context) error // 执行函数，ctx在队列关闭时取消
+}
+
+// PoolOptions 工作池选项
+type PoolOptions struct {
+	Concurrency      int // 同时执行的任务数
+	BatchConcurrency int // 批量任务最多占用的并发数
+	QueueSize        int // 排队任务上限
+}
+
+// Options 任务队列选项
+type Options struct {
+	Pools map[string]PoolOptions // 按任务类型配置的工作池，未配置的类型使用默认值
+}
+
+// OptionsFromConfig 从任务队列配置生成队列选项
+func OptionsFromConfig(cfg config.JobsConfig) Options {
+	options := Options{Pools: make(map[string]PoolOptions, len(cfg.Pools))}
+	for jobType, pool := range cfg.Pools {
+		options.Pools[jobType] = PoolOptions{
+			Concurrency:      pool.Concurrency,
+			BatchConcurrency: pool.BatchConcurrency,
+			QueueSize:        pool.QueueSize,
+		}
+	}
+	return options
+}
+
+// withDefaults 补全工作池默认值
+//
+// 批量任务并发数默认比总并发数少1；总并发数为1时批量任务也可使用唯一的并发位，
+// 此时预览任务最多等待一个正在执行的批量任务
+func (o PoolOptions) withDefaults() PoolOptions {
+	if o.Concurrency <= 0 {
+		o.Concurrency = defaultConcurrency
+	}
+	if o.BatchConcurrency <= 0 {
+		o.BatchConcurrency = max(o.Concurrency-1, 1)
+	}
+	o.BatchConcurrency = min(o.BatchConcurrency, o.Concurrency)
+	if o.QueueSize <= 0 {
+		o.QueueSize = defaultQueueSize
+	}
+	return o
+}
+
+// PoolMetrics 工作池指标，用于观察积压和背压
+type PoolMetrics struct {
+	Type              string `json:"type"`               // 任务类型
+	Concurrency       int    `json:"concurrency"`        // 并发上限
+	BatchConcurrency  int    `json:"batch_concurrency"`  // 批量任务并发上限
+	QueueSize         int    `json:"queue_size"`         // 排队上限
+	Running           int    `json:"running"`            // 正在执行的任务数
+	RunningBatch      int    `json:"running_batch"`      // 正在执行的批量任务数
+	QueuedInteractive int    `json:"queued_interactive"` // 排队的预览任务数
+	QueuedBatch       int    `json:"queued_batch"`       // 排队的批量任务数
+	OldestQueued      string `json:"oldest_queued"`      // 最早排队任务已等待的时间
+	AverageWait       string `json:"average_wait"`       // 已开始任务的平均排队时间
+	Completed         int64  `json:"completed"`          // 累计成功数
+	Failed            int64  `json:"failed"`             // 累计失败数(含panic)
+	Rejected          int64  `json:"rejected"`           // 队列满被拒绝的任务数
+	Dropped           int64  `json:"dropped"`            // 关闭时丢弃的排队任务数
+}
+
+// queuedJob 排队中的任务
+type queuedJob struct {
+	job        Job
+	enqueuedAt time.Time
+}
+
+// pool 单个任务类型的工作池
+type pool struct {
+	options     PoolOptions
+	interactive []queuedJob
+	batch       []queuedJob
+	metrics     PoolMetrics
+	started     int64
+	totalWait   time.Duration
+}
+
+// Queue 进程内后台任务队列
+//
+// 每个任务类型一个工作池，任务按 预览任务 > 批量任务 的顺序、同优先级先进先出地启动，
+// 同时执行的任务数不超过工作池并发上限。Start之前加入的任务排队等待，关闭时丢弃未开始的任务
+//
+// 使用示例：
+//
+//	queue := jobs.NewQueue(jobs.OptionsFromConfig(cfg.Jobs), logger)
+//	queue.Start(ctx)
+//	err := queue.Enqueue(jobs.Job{Type: jobs.TypeThumbnail, Priority: jobs.PriorityInteractive, Key: "file:1", Run: generate})
+//	metrics := queue.Metrics()
+type Queue struct {
+	options Options
+	logger  *zap.Logger
+	now     func() time.Time
+
+	mu      sync.Mutex
+	pools   map[string]*pool
+	ctx     context.Context
+	closed  bool
+	running sync.WaitGroup
+}
+
+// NewQueue 创建后台任务队列，配置中的工作池立即创建，其他类型在首次加入任务时按默认值创建
+func NewQueue(options Options, logger *zap.Logger) *Queue {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	q := &Queue{
+		options: options,
+		logger:  logger,
+		now:     time.Now,
+		pools:   make(map[string]*pool),
+	}
+	for jobType := range options.Pools {
+		q.pool(jobType)
+	}
+	return q
+}
+
+// Enqueue 将任务加入对应类型的工作池
+//
+// 工作池排队任务达到上限时返回 ErrQueueFull，队列关闭后返回 ErrQueueClosed
+func (q *Queue) Enqueue(job Job) error {
+	if job.Type == "" || job.Run == nil {
+		return fmt.Errorf("任务类型和执行函数不能为空")
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrQueueClosed
+	}
+
+	p := q.pool(job.Type)
+	if len(p.interactive)+len(p.batch) >= p.options.QueueSize {
+		p.metrics.Rejected++
+		return ErrQueueFull
+	}
+	queued := queuedJob{job: job, enqueuedAt: q.now()}
+	if job.Priority == PriorityInteractive {
+		p.interactive = append(p.interactive, queued)
+	} else {
+		p.batch = append(p.batch, queued)
+	}
+	q.schedule(p)
+	return nil
+}
+
+// Start 开始执行任务，ctx取消后关闭队列并取消正在执行的任务
+func (q *Queue) Start(ctx context.Context) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.ctx != nil || q.closed {
+		return
+	}
+	q.ctx = ctx
+	for _, p := range q.pools {
+		q.schedule(p)
+	}
+	go func() {
+		<-ctx.Done()
+		q.Close()
+	}()
+	q.logger.Info("Job queue started", zap.Int("pools", len(q.pools)))
+}
+
+// Close 停止接收新任务并丢弃未开始的任务，正在执行的任务继续运行直到完成或ctx取消
+func (q *Queue) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return
+	}
+	q.closed = true
+	for jobType, p := range q.pools {
+		dropped := len(p.interactive) + len(p.batch)
+		if dropped == 0 {
+			continue
+		}
+		p.metrics.Dropped += int64(dropped)
+		p.interactive, p.batch = nil, nil
+		q.logger.Warn("Queued jobs dropped on shutdown", zap.String("type", jobType), zap.Int("jobs", dropped))
+	}
+}
+
+// Wait 等待正在执行的任务结束，应在Close或取消Start的ctx后调用
+func (q *Queue) Wait() {
+	q.running.Wait()
+}
+
+// Metrics 返回各工作池的指标，按任务类型排序
+func (q *Queue) Metrics() []PoolMetrics {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now()
+	metrics := make([]PoolMetrics, 0, len(q.pools))
+	for _, p := range q.pools {
+		m := p.metrics
+		m.QueuedInteractive = len(p.interactive)
+		m.QueuedBatch = len(p.batch)
+		var oldest time.Duration
+		for _, queue := range [][]queuedJob{p.interactive, p.batch} {
+			if len(queue) > 0 {
+				oldest = max(oldest, now.Sub(queue[0].enqueuedAt))
+			}
+		}
+		m.OldestQueued = oldest.String()
+		var average time.Duration
+		if p.started > 0 {
+			average = p.totalWait / time.Duration(p.started)
+		}
+		m.AverageWait = average.String()
+		metrics = append(metrics, m)
+	}
+	sort.Slice(metrics, func(i, j int) bool { return metrics[i].Type < metrics[j].Type })
+	return metrics
+}
+
+// pool 返回任务类型的工作池，不存在时按配置或默认值创建，调用方需持有锁
+func (q *Queue) pool(jobType string) *pool {
+	if p, ok := q.pools[jobType]; ok {
+		return p
+	}
+	options := q.options.Pools[jobType].withDefaults()
+	p := &pool{
+		options: options,
+		metrics: PoolMetrics{
+			Type:             jobType,
+			Concurrency:      options.Concurrency,
+			BatchConcurrency: options.BatchConcurrency,
+			QueueSize:        options.QueueSize,
+		},
+	}
+	q.pools[jobType] = p
+	return p
+}
+
+// schedule 在并发上限内启动排队的任务，预览任务优先，调用方需持有锁
+func (q *Queue) schedule(p *pool) {
+	if q.ctx == nil || q.closed {
+		return
+	}
+	for p.metrics.Running < p.options.Concurrency {
+		var next queuedJob
+		switch {
+		case len(p.interactive) > 0:
+			next, p.interactive = p.interactive[0], p.interactive[1:]
+		case len(p.batch) > 0 && p.metrics.RunningBatch < p.options.BatchConcurrency:
+			next, p.batch = p.batch[0], p.batch[1:]
+			p.metrics.RunningBatch++
+		default:
+			return
+		}
+
+		p.metrics.Running++
+		p.started++
+		p.totalWait += q.now().Sub(next.enqueuedAt)
+		q.running.Add(1)
+		go q.execute(q.ctx, p, next.job)
+	}
+}
+
+// execute 执行任务并更新指标，完成后启动下一个排队的任务
+func (q *Queue) execute(ctx context.Context, p *pool, job Job) {
+	defer q.running.Done()
+
+	err := runJob(ctx, job)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	p.metrics.Running--
+	if job.Priority != PriorityInteractive {
+		p.metrics.RunningBatch--
+	}
+	if err != nil {
+		p.metrics.Failed++
+		q.logger.Warn("Job failed",
+			zap.String("type", job.Type),
+			zap.String("key", job.Key),
+			zap.String("priority", job.Priority.String()),
+			zap.Error(err))
+	} else {
+		p.metrics.Completed++
+	}
+	q.schedule(p)
+}
+
+// runJob 执行任务，任务panic时转为错误，避免媒体处理库的异常导致服务退出
+func runJob(ctx context.Context, job Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("任务panic: %v", r)
+		}
+	}()
+	return job.Run(ctx)
+}
+
+var (
+	defaultMu    sync.RWMutex
+	defaultQueue *Queue
+)
+
+// SetDefault 设置全局后台任务队列，启动时由main调用
+func SetDefault(queue *Queue) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultQueue = queue
+}
+
+// Default 返回全局后台任务队列，未创建时返回nil
+func Default() *Queue {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultQueue
+}
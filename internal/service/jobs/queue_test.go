@@ -0,0 +1,112 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueue_InteractiveBeforeBatch(t *testing.T) {
+	q := NewQueue(Options{Pools: map[string]PoolOptions{TypeThumbnail: {Concurrency: 1}}}, nil)
+
+	var mu sync.Mutex
+	var order []string
+	record := func(key string) func(context.Context) error {
+		return func(context.Context) error {
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, key)
+			return nil
+		}
+	}
+	// Start之前加入的任务排队等待
+	require.NoError(t, q.Enqueue(Job{Type: TypeThumbnail, Priority: PriorityBatch, Key: "b1", Run: record("b1")}))
+	require.NoError(t, q.Enqueue(Job{Type: TypeThumbnail, Priority: PriorityBatch, Key: "b2", Run: record("b2")}))
+	require.NoError(t, q.Enqueue(Job{Type: TypeThumbnail, Priority: PriorityInteractive, Key: "i1", Run: record("i1")}))
+
+	q.Start(context.Background())
+	q.Wait()
+	q.Close()
+
+	assert.Equal(t, []string{"i1", "b1", "b2"}, order)
+	metrics := q.Metrics()
+	require.Len(t, metrics, 1)
+	assert.Equal(t, int64(3), metrics[0].Completed)
+}
+
+func TestQueue_BatchLeavesRoomForInteractive(t *testing.T) {
+	q := NewQueue(Options{Pools: map[string]PoolOptions{TypeTranscode: {Concurrency: 2}}}, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	q.Start(ctx)
+
+	release := make(chan struct{})
+	started := make(chan string, 3)
+	blocking := func(key string) func(context.Context) error {
+		return func(context.Context) error {
+			started <- key
+			<-release
+			return nil
+		}
+	}
+
+	require.NoError(t, q.Enqueue(Job{Type: TypeTranscode, Priority: PriorityBatch, Key: "b1", Run: blocking("b1")}))
+	require.NoError(t, q.Enqueue(Job{Type: TypeTranscode, Priority: PriorityBatch, Key: "b2", Run: blocking("b2")}))
+	assert.Equal(t, "b1", <-started)
+
+	// 批量任务最多占用一个并发位，第二个批量任务排队
+	metrics := q.Metrics()[0]
+	assert.Equal(t, 1, metrics.BatchConcurrency)
+	assert.Equal(t, 1, metrics.Running)
+	assert.Equal(t, 1, metrics.QueuedBatch)
+
+	// 预览任务使用保留的并发位立即执行
+	require.NoError(t, q.Enqueue(Job{Type: TypeTranscode, Priority: PriorityInteractive, Key: "i1", Run: blocking("i1")}))
+	assert.Equal(t, "i1", <-started)
+	assert.Equal(t, 2, q.Metrics()[0].Running)
+
+	close(release)
+	assert.Equal(t, "b2", <-started)
+	q.Close()
+	q.Wait()
+	assert.Equal(t, int64(3), q.Metrics()[0].Completed)
+}
+
+func TestQueue_Backpressure(t *testing.T) {
+	q := NewQueue(Options{Pools: map[string]PoolOptions{"scan": {QueueSize: 1}}}, nil)
+	noop := func(context.Context) error { return nil }
+
+	require.NoError(t, q.Enqueue(Job{Type: "scan", Run: noop}))
+	assert.ErrorIs(t, q.Enqueue(Job{Type: "scan", Run: noop}), ErrQueueFull)
+
+	metrics := q.Metrics()[0]
+	assert.Equal(t, "scan", metrics.Type)
+	assert.Equal(t, int64(1), metrics.Rejected)
+	assert.Equal(t, 1, metrics.QueuedBatch)
+
+	// 关闭时丢弃未开始的任务
+	q.Close()
+	assert.ErrorIs(t, q.Enqueue(Job{Type: "scan", Run: noop}), ErrQueueClosed)
+	assert.Equal(t, int64(1), q.Metrics()[0].Dropped)
+}
+
+func TestQueue_FailuresAndPanics(t *testing.T) {
+	// 未配置的类型使用默认工作池
+	q := NewQueue(Options{}, nil)
+	q.Start(context.Background())
+
+	require.NoError(t, q.Enqueue(Job{Type: TypeThumbnail, Run: func(context.Context) error { return errors.New("decode failed") }}))
+	require.NoError(t, q.Enqueue(Job{Type: TypeThumbnail, Run: func(context.Context) error { panic("corrupt image") }}))
+	assert.Error(t, q.Enqueue(Job{Type: TypeThumbnail}))
+
+	q.Wait()
+	q.Close()
+	metrics := q.Metrics()[0]
+	assert.Equal(t, defaultConcurrency, metrics.Concurrency)
+	assert.Equal(t, int64(2), metrics.Failed)
+	assert.Equal(t, 0, metrics.Running)
+}
@@ -0,0 +1,83 @@
+package limits
+
+import (
+	"context"
+	"time"
+
+	"cloudpan/internal/repository/models"
+)
+
+// LimitsService 有效限制解析服务接口
+//
+// 按 配置默认值 -> 系统设置 -> 用户配额 的顺序解析当前用户的有效限制，
+// 供客户端自动配置上传分片、并发、文件类型等参数。解析结果按用户缓存
+//
+// 使用示例：
+//
+//	service := NewLimitsService(userRepo, settingRepo, cacheManager, logger)
+//	limits, err := service.GetEffectiveLimits(ctx, userID)
+//	err = service.Invalidate(ctx, userID)
+type LimitsService interface {
+	GetEffectiveLimits(ctx context.Context, userID uint) (*EffectiveLimits, error)
+	Invalidate(ctx context.Context, userID uint) error
+}
+
+// UserReader 读取用户信息，由用户仓储实现
+type UserReader interface {
+	GetByID(ctx context.Context, id uint) (*models.User, error)
+}
+
+// SettingReader 读取系统设置，由系统设置仓储实现
+type SettingReader interface {
+	GetSetting(ctx context.Context, category, key string) (*models.SystemSetting, error)
+}
+
+// EffectiveLimits 当前用户的有效限制
+type EffectiveLimits struct {
+	Upload     UploadLimits      `json:"upload"`      // 上传限制
+	Storage    StorageLimits     `json:"storage"`     // 存储配额
+	RateLimit  RateLimits        `json:"rate_limit"`  // 请求限流
+	Share      ShareLimits       `json:"share"`       // 分享限制
+	ResolvedAt time.Time         `json:"resolved_at"` // 解析时间
+	Sources    map[string]string `json:"sources"`     // 各限制项的来源(config/setting/user)
+}
+
+// UploadLimits 上传限制
+type UploadLimits struct {
+	MaxFileSize         int64    `json:"max_file_size"`         // 单文件最大字节数(已考虑剩余配额，-1表示不限制)
+	ChunkSize           int64    `json:"chunk_size"`            // 推荐分片大小
+	MaxParallelism      int      `json:"max_parallelism"`       // 最大并行上传分片数
+	ChunkHashAlgorithms []string `json:"chunk_hash_algorithms"` // 支持的分片校验算法(按优先级)
+	AllowedTypes        []string `json:"allowed_types"`         // 允许的MIME类型(为空表示不限制)
+}
+
+// StorageLimits 存储配额
+type StorageLimits struct {
+	Quota     int64 `json:"quota"`     // 总配额
+	Used      int64 `json:"used"`      // 已使用
+	Remaining int64 `json:"remaining"` // 剩余可用
+}
+
+// RateLimits 请求限流
+type RateLimits struct {
+	Enabled           bool `json:"enabled"`             // 是否启用限流
+	RequestsPerMinute int  `json:"requests_per_minute"` // 每分钟请求数
+	Burst             int  `json:"burst"`               // 突发请求数
+}
+
+// ShareLimits 分享限制
+type ShareLimits struct {
+	MaxActiveShares int `json:"max_active_shares"` // 最多同时有效的分享数(0表示不限制)
+	MaxExpireDays   int `json:"max_expire_days"`   // 最长有效天数(0表示允许永久)
+}
+
+// UnlimitedSize 表示大小不受限制
+const UnlimitedSize int64 = -1
+
+// 限制项来源
+const (
+	SourceDefault = "default" // 内置默认值
+	SourceConfig  = "config"  // 配置文件
+	SourceSetting = "setting" // 系统设置
+	SourceUser    = "user"    // 用户配额
+)
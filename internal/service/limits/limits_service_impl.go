@@ -0,0 +1,220 @@
+package limits
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/cache"
+	"cloudpan/internal/pkg/config"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/repository/models"
+	filesvc "cloudpan/internal/service/file"
+)
+
+// 配置缺失时使用的内置默认值
+const (
+	defaultChunkSize      int64 = 5 * 1024 * 1024 // 5MB
+	defaultMaxParallelism       = 4
+)
+
+// limitsService 有效限制解析服务实现
+type limitsService struct {
+	userRepo     UserReader
+	settingRepo  SettingReader
+	cacheManager *cache.CacheManager
+	keyBuilder   *cache.KeyBuilder
+	ttl          time.Duration
+	logger       *zap.Logger
+}
+
+// NewLimitsService 创建有效限制解析服务实例
+//
+// cacheManager 可以为nil(如Redis未初始化)，此时每次请求都重新解析
+func NewLimitsService(userRepo UserReader, settingRepo SettingReader, cacheManager *cache.CacheManager, logger *zap.Logger) LimitsService {
+	return &limitsService{
+		userRepo:     userRepo,
+		settingRepo:  settingRepo,
+		cacheManager: cacheManager,
+		keyBuilder:   cache.NewKeyBuilder(),
+		ttl:          cache.NewTTLManager().GetTTL("user_limits"),
+		logger:       logger,
+	}
+}
+
+// GetEffectiveLimits 获取当前用户的有效限制
+func (s *limitsService) GetEffectiveLimits(ctx context.Context, userID uint) (*EffectiveLimits, error) {
+	if userID == 0 {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "用户ID不能为空")
+	}
+
+	cacheKey := s.keyBuilder.UserLimits(strconv.FormatUint(uint64(userID), 10))
+	if s.cacheManager != nil {
+		var cached EffectiveLimits
+		if err := s.cacheManager.Get(cacheKey, &cached); err == nil {
+			return &cached, nil
+		}
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "用户不存在")
+		}
+		return nil, fmt.Errorf("获取用户失败: %w", err)
+	}
+
+	limits := s.resolve(ctx, user)
+
+	if s.cacheManager != nil {
+		if err := s.cacheManager.SetWithTTL(cacheKey, limits, s.ttl); err != nil {
+			s.logger.Warn("Failed to cache effective limits",
+				zap.Uint("user_id", userID),
+				zap.Error(err))
+		}
+	}
+
+	return limits, nil
+}
+
+// Invalidate 清除用户的有效限制缓存(配额或策略变更后调用)
+func (s *limitsService) Invalidate(_ context.Context, userID uint) error {
+	if s.cacheManager == nil {
+		return nil
+	}
+	return s.cacheManager.Delete(s.keyBuilder.UserLimits(strconv.FormatUint(uint64(userID), 10)))
+}
+
+// resolve 按 配置 -> 系统设置 -> 用户配额 的顺序解析限制
+func (s *limitsService) resolve(ctx context.Context, user *models.User) *EffectiveLimits {
+	cfg := config.AppConfig
+	if cfg == nil {
+		cfg = &config.Config{}
+	}
+	sources := make(map[string]string)
+
+	// 1. 配置文件默认值
+	maxFileSize := cfg.Storage.Local.MaxSize
+	sources["max_file_size"] = SourceConfig
+	if maxFileSize <= 0 {
+		maxFileSize = UnlimitedSize
+		sources["max_file_size"] = SourceDefault
+	}
+	allowedTypes := cfg.Storage.Local.AllowedTypes
+	sources["allowed_types"] = SourceConfig
+
+	chunkSize := cfg.Storage.Upload.ChunkSize
+	sources["chunk_size"] = SourceConfig
+	if chunkSize <= 0 {
+		chunkSize = defaultChunkSize
+		sources["chunk_size"] = SourceDefault
+	}
+
+	parallelism := cfg.Storage.Upload.MaxParallelism
+	sources["max_parallelism"] = SourceConfig
+	if parallelism <= 0 {
+		parallelism = defaultMaxParallelism
+		sources["max_parallelism"] = SourceDefault
+	}
+
+	// 2. 管理员在系统设置中的覆盖值
+	if value, ok := s.getSetting(ctx, models.SettingCategoryStorage, models.SettingKeyMaxFileSize); ok {
+		if size, err := strconv.ParseInt(value, 10, 64); err == nil && size > 0 {
+			maxFileSize = size
+			sources["max_file_size"] = SourceSetting
+		}
+	}
+	if value, ok := s.getSetting(ctx, models.SettingCategoryStorage, models.SettingKeyAllowedFileTypes); ok {
+		allowedTypes = parseTypeList(value)
+		sources["allowed_types"] = SourceSetting
+	}
+
+	// 3. 用户配额：单文件大小不能超过剩余空间
+	remaining := user.StorageQuota - user.StorageUsed
+	if remaining < 0 {
+		remaining = 0
+	}
+	if user.StorageQuota > 0 && (maxFileSize == UnlimitedSize || remaining < maxFileSize) {
+		maxFileSize = remaining
+		sources["max_file_size"] = SourceUser
+	}
+
+	if allowedTypes == nil {
+		allowedTypes = []string{}
+	}
+
+	return &EffectiveLimits{
+		Upload: UploadLimits{
+			MaxFileSize:         maxFileSize,
+			ChunkSize:           chunkSize,
+			MaxParallelism:      parallelism,
+			ChunkHashAlgorithms: filesvc.SupportedChunkHashAlgorithms(),
+			AllowedTypes:        allowedTypes,
+		},
+		Storage: StorageLimits{
+			Quota:     user.StorageQuota,
+			Used:      user.StorageUsed,
+			Remaining: remaining,
+		},
+		RateLimit: userRateLimits(cfg.Security.RateLimit),
+		Share: ShareLimits{
+			MaxActiveShares: cfg.Share.MaxActiveShares,
+			MaxExpireDays:   cfg.Share.MaxExpireDays,
+		},
+		ResolvedAt: time.Now(),
+		Sources:    sources,
+	}
+}
+
+// getSetting 读取系统设置值，设置不存在或读取失败时返回false
+func (s *limitsService) getSetting(ctx context.Context, category, key string) (string, bool) {
+	if s.settingRepo == nil {
+		return "", false
+	}
+
+	setting, err := s.settingRepo.GetSetting(ctx, category, key)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			s.logger.Warn("Failed to read system setting",
+				zap.String("category", category),
+				zap.String("key", key),
+				zap.Error(err))
+		}
+		return "", false
+	}
+
+	value := strings.TrimSpace(setting.GetStringValue())
+	return value, value != ""
+}
+
+// parseTypeList 解析类型列表，支持JSON数组或逗号分隔
+func parseTypeList(value string) []string {
+	var types []string
+	if strings.HasPrefix(value, "[") {
+		if err := json.Unmarshal([]byte(value), &types); err == nil {
+			return types
+		}
+	}
+
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			types = append(types, item)
+		}
+	}
+	return types
+}
+
+// userRateLimits 登录用户生效的限流规则，配置了用户限流时按用户计数，否则按IP计数
+func userRateLimits(cfg config.RateLimitConfig) RateLimits {
+	if cfg.UserRequestsPerMinute > 0 {
+		return RateLimits{Enabled: cfg.Enabled, RequestsPerMinute: cfg.UserRequestsPerMinute, Burst: cfg.UserBurst}
+	}
+	return RateLimits{Enabled: cfg.Enabled, RequestsPerMinute: cfg.RequestsPerMinute, Burst: cfg.Burst}
+}
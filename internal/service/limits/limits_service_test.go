@@ -0,0 +1,131 @@
+package limits
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/config"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/repository/models"
+)
+
+// MockUserReader 模拟用户读取
+type MockUserReader struct {
+	mock.Mock
+}
+
+func (m *MockUserReader) GetByID(ctx context.Context, id uint) (*models.User, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+// MockSettingReader 模拟系统设置读取
+type MockSettingReader struct {
+	mock.Mock
+}
+
+func (m *MockSettingReader) GetSetting(ctx context.Context, category, key string) (*models.SystemSetting, error) {
+	args := m.Called(ctx, category, key)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.SystemSetting), args.Error(1)
+}
+
+func setupLimitsConfig(t *testing.T) {
+	original := config.AppConfig
+	t.Cleanup(func() { config.AppConfig = original })
+
+	config.AppConfig = &config.Config{
+		Storage: config.StorageConfig{
+			Local: config.LocalStorageConfig{
+				MaxSize:      1000,
+				AllowedTypes: []string{"image/png"},
+			},
+			Upload: config.UploadConfig{ChunkSize: 100},
+		},
+		Security: config.SecurityConfig{
+			RateLimit: config.RateLimitConfig{Enabled: true, RequestsPerMinute: 60, Burst: 10},
+		},
+		Share: config.ShareConfig{MaxActiveShares: 50, MaxExpireDays: 30},
+	}
+}
+
+func newTestUser(quota, used int64) *models.User {
+	user := &models.User{StorageQuota: quota, StorageUsed: used}
+	user.ID = 1
+	return user
+}
+
+func TestGetEffectiveLimits_ConfigDefaults(t *testing.T) {
+	setupLimitsConfig(t)
+	ctx := context.Background()
+
+	users := new(MockUserReader)
+	settings := new(MockSettingReader)
+	users.On("GetByID", ctx, uint(1)).Return(newTestUser(0, 0), nil)
+	settings.On("GetSetting", ctx, mock.Anything, mock.Anything).Return(nil, gorm.ErrRecordNotFound)
+
+	limits, err := NewLimitsService(users, settings, nil, zap.NewNop()).GetEffectiveLimits(ctx, 1)
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(1000), limits.Upload.MaxFileSize)
+	assert.Equal(t, int64(100), limits.Upload.ChunkSize)
+	assert.Equal(t, defaultMaxParallelism, limits.Upload.MaxParallelism)
+	assert.Equal(t, SourceDefault, limits.Sources["max_parallelism"])
+	assert.Equal(t, []string{"image/png"}, limits.Upload.AllowedTypes)
+	assert.NotEmpty(t, limits.Upload.ChunkHashAlgorithms)
+	assert.Equal(t, 60, limits.RateLimit.RequestsPerMinute)
+	assert.Equal(t, 50, limits.Share.MaxActiveShares)
+}
+
+func TestGetEffectiveLimits_SettingsAndQuota(t *testing.T) {
+	setupLimitsConfig(t)
+	ctx := context.Background()
+
+	maxSize := "5000"
+	types := `["video/mp4","image/jpeg"]`
+
+	users := new(MockUserReader)
+	settings := new(MockSettingReader)
+	users.On("GetByID", ctx, uint(1)).Return(newTestUser(3000, 1000), nil)
+	settings.On("GetSetting", ctx, models.SettingCategoryStorage, models.SettingKeyMaxFileSize).
+		Return(&models.SystemSetting{Value: &maxSize}, nil)
+	settings.On("GetSetting", ctx, models.SettingCategoryStorage, models.SettingKeyAllowedFileTypes).
+		Return(&models.SystemSetting{Value: &types}, nil)
+
+	limits, err := NewLimitsService(users, settings, nil, zap.NewNop()).GetEffectiveLimits(ctx, 1)
+	require.NoError(t, err)
+
+	// 系统设置放宽到5000，但剩余配额只有2000
+	assert.Equal(t, int64(2000), limits.Upload.MaxFileSize)
+	assert.Equal(t, SourceUser, limits.Sources["max_file_size"])
+	assert.Equal(t, []string{"video/mp4", "image/jpeg"}, limits.Upload.AllowedTypes)
+	assert.Equal(t, SourceSetting, limits.Sources["allowed_types"])
+	assert.Equal(t, int64(2000), limits.Storage.Remaining)
+}
+
+func TestGetEffectiveLimits_UserNotFound(t *testing.T) {
+	setupLimitsConfig(t)
+	ctx := context.Background()
+
+	users := new(MockUserReader)
+	users.On("GetByID", ctx, uint(9)).Return(nil, gorm.ErrRecordNotFound)
+
+	_, err := NewLimitsService(users, nil, nil, zap.NewNop()).GetEffectiveLimits(ctx, 9)
+	assert.True(t, pkgErrors.IsNotFoundError(err))
+}
+
+func TestParseTypeList(t *testing.T) {
+	assert.Equal(t, []string{"a/b", "c/d"}, parseTypeList("a/b, c/d,"))
+	assert.Equal(t, []string{"a/b"}, parseTypeList(`["a/b"]`))
+}
@@ -0,0 +1,41 @@
+package maintenance
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"cloudpan/internal/pkg/cache"
+)
+
+// RedisLocker 基于Redis的任务分布式锁
+//
+// 与文件锁相同的键格式(lock:task:<任务名称>)，值为持有锁的实例标识，便于排查由哪个实例执行
+type RedisLocker struct {
+	client   *redis.Client
+	instance string
+}
+
+// NewRedisLocker 创建基于Redis的任务分布式锁
+func NewRedisLocker(client *redis.Client) *RedisLocker {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return &RedisLocker{
+		client:   client,
+		instance: fmt.Sprintf("%s:%d", host, os.Getpid()),
+	}
+}
+
+// TryLock 尝试获取任务锁，锁在ttl后自动过期
+func (l *RedisLocker) TryLock(ctx context.Context, name string, ttl time.Duration) (bool, error) {
+	acquired, err := l.client.SetNX(ctx, cache.Keys.TaskLock(name), l.instance, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("获取维护任务锁失败: %w", err)
+	}
+	return acquired, nil
+}
@@ -0,0 +1,361 @@
+package maintenance
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/config"
+)
+
+// 配置缺失时使用的内置默认值
+const (
+	defaultInterval  = time.Hour
+	defaultJitter    = 0.1
+	defaultBatchSize = 500
+)
+
+// 任务状态
+const (
+	StatusPending = "pending" // 尚未执行
+	StatusRunning = "running" // 正在执行
+	StatusOK      = "ok"      // 最近一次执行成功
+	StatusFailed  = "failed"  // 最近一次执行失败
+	StatusSkipped = "skipped" // 最近一次由其他实例执行，本实例跳过
+)
+
+// Task 定期维护任务
+type Task struct {
+	Name     string                                   // 任务名称，唯一
+	Interval time.Duration                            // 执行间隔，为0时使用调度器的默认间隔；配置中按任务名称设置的间隔优先
+	Local    bool                                     // 只处理本进程内的数据，每个实例都执行，不加分布式锁
+	Run      func(ctx context.Context) (int64, error) // 执行一次清理，返回清理的记录数
+}
+
+// Locker 任务分布式锁，多实例部署时同一任务在一个执行间隔内只由一个实例执行
+type Locker interface {
+	// TryLock 尝试获取任务锁，锁在ttl后自动过期，不主动释放
+	TryLock(ctx context.Context, name string, ttl time.Duration) (bool, error)
+}
+
+// TaskMetrics 任务执行指标
+type TaskMetrics struct {
+	Name         string     `json:"name"`                  // 任务名称
+	Interval     string     `json:"interval"`              // 执行间隔
+	Local        bool       `json:"local"`                 // 是否每个实例都执行
+	Status       string     `json:"status"`                // 任务状态：pending、running、ok、failed、skipped
+	Healthy      bool       `json:"healthy"`               // 最近一次执行未失败且没有超过一个间隔仍未结束
+	Runs         int64      `json:"runs"`                  // 累计执行次数
+	Failures     int64      `json:"failures"`              // 累计失败次数
+	Skipped      int64      `json:"skipped"`               // 累计因其他实例持有任务锁而跳过的次数
+	Removed      int64      `json:"removed"`               // 累计清理记录数
+	LastRemoved  int64      `json:"last_removed"`          // 最近一次清理记录数
+	LastRunAt    *time.Time `json:"last_run_at,omitempty"` // 最近一次开始时间
+	LastDuration string     `json:"last_duration"`         // 最近一次耗时
+	LastError    string     `json:"last_error,omitempty"`  // 最近一次失败原因，成功后清空
+	NextRunAt    *time.Time `json:"next_run_at,omitempty"` // 下一次计划执行时间
+}
+
+// Options 调度选项
+type Options struct {
+	Interval  time.Duration            // 任务默认执行间隔
+	Intervals map[string]time.Duration // 按任务名称设置的执行间隔
+	Jitter    float64                  // 间隔随机浮动比例(0-1)
+	BatchSize int                      // 清理任务每批处理的记录数
+}
+
+// OptionsFromConfig 从维护配置生成调度选项
+func OptionsFromConfig(cfg config.MaintenanceConfig) Options {
+	return Options{
+		Interval:  cfg.Interval,
+		Intervals: cfg.Tasks,
+		Jitter:    cfg.Jitter,
+		BatchSize: cfg.BatchSize,
+	}
+}
+
+// taskState 任务及其执行指标
+type taskState struct {
+	task    Task
+	metrics TaskMetrics
+	running bool
+}
+
+// Scheduler 定期维护任务调度器
+//
+// 每个任务在独立的goroutine中按各自的间隔执行，同一任务不会并发执行。
+// 每次等待的间隔在 [1-jitter, 1+jitter] 倍之间随机浮动，首次执行也在一个间隔后，
+// 多个实例同时启动时清理操作会被错开，避免同时冲击数据库。
+// 设置任务锁后，非本地任务执行前获取锁，锁在约一个间隔后过期，同一间隔内其他实例跳过执行；
+// 获取锁出错时仍然执行，清理操作都是幂等的，多实例重复执行只会产生空操作
+//
+// 使用示例：
+//
+//	scheduler := NewScheduler(OptionsFromConfig(cfg), logger)
+//	scheduler.SetLocker(NewRedisLocker(cache.RedisClient))
+//	RegisterCleanupTasks(scheduler, Sources{...})
+//	scheduler.Start(ctx)
+//	metrics := scheduler.Metrics()
+type Scheduler struct {
+	options Options
+	logger  *zap.Logger
+	now     func() time.Time
+	random  func() float64
+	locker  Locker
+
+	mu      sync.Mutex
+	tasks   map[string]*taskState
+	ctx     context.Context
+	running sync.WaitGroup
+}
+
+// NewScheduler 创建定期维护任务调度器
+func NewScheduler(options Options, logger *zap.Logger) *Scheduler {
+	if options.Interval <= 0 {
+		options.Interval = defaultInterval
+	}
+	if options.Jitter <= 0 || options.Jitter >= 1 {
+		options.Jitter = defaultJitter
+	}
+	if options.BatchSize <= 0 {
+		options.BatchSize = defaultBatchSize
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &Scheduler{
+		options: options,
+		logger:  logger,
+		now:     time.Now,
+		random:  rand.Float64,
+		tasks:   make(map[string]*taskState),
+	}
+}
+
+// SetLocker 设置任务分布式锁，需在Start前调用；未设置时每个实例独立执行全部任务
+func (s *Scheduler) SetLocker(locker Locker) {
+	s.locker = locker
+}
+
+// Interval 返回任务默认执行间隔
+func (s *Scheduler) Interval() time.Duration {
+	return s.options.Interval
+}
+
+// BatchSize 返回清理任务每批处理的记录数
+func (s *Scheduler) BatchSize() int {
+	return s.options.BatchSize
+}
+
+// Register 注册任务，同名任务重复注册时返回错误
+//
+// 调度器已启动时立即开始调度新任务
+func (s *Scheduler) Register(task Task) error {
+	if task.Name == "" || task.Run == nil {
+		return fmt.Errorf("维护任务名称和执行函数不能为空")
+	}
+	if interval := s.options.Intervals[task.Name]; interval > 0 {
+		task.Interval = interval
+	}
+	if task.Interval <= 0 {
+		task.Interval = s.options.Interval
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.tasks[task.Name]; exists {
+		return fmt.Errorf("维护任务已注册: %s", task.Name)
+	}
+	state := &taskState{task: task, metrics: TaskMetrics{
+		Name:     task.Name,
+		Interval: task.Interval.String(),
+		Local:    task.Local,
+		Status:   StatusPending,
+	}}
+	s.tasks[task.Name] = state
+	if s.ctx != nil {
+		s.launch(s.ctx, state)
+	}
+	return nil
+}
+
+// Start 开始调度已注册的任务，ctx取消后停止
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ctx != nil {
+		return
+	}
+	s.ctx = ctx
+	for _, state := range s.tasks {
+		s.launch(ctx, state)
+	}
+	s.logger.Info("Maintenance scheduler started",
+		zap.Int("tasks", len(s.tasks)),
+		zap.Duration("interval", s.options.Interval),
+		zap.Float64("jitter", s.options.Jitter),
+		zap.Bool("distributed_lock", s.locker != nil))
+	if s.locker == nil {
+		s.logger.Warn("Maintenance scheduler running without distributed lock, every instance runs every task; configure Redis for multi-instance deployments")
+	}
+}
+
+// Wait 等待所有任务的goroutine退出，应在取消Start的ctx后调用
+func (s *Scheduler) Wait() {
+	s.running.Wait()
+}
+
+// Metrics 返回各任务的执行指标，按任务名称排序
+func (s *Scheduler) Metrics() []TaskMetrics {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	metrics := make([]TaskMetrics, 0, len(s.tasks))
+	for _, state := range s.tasks {
+		item := state.metrics
+		item.Healthy = item.Status != StatusFailed
+		// 执行超过一个间隔仍未结束视为卡住
+		if state.running && item.LastRunAt != nil && now.Sub(*item.LastRunAt) > state.task.Interval {
+			item.Healthy = false
+		}
+		metrics = append(metrics, item)
+	}
+	sort.Slice(metrics, func(i, j int) bool { return metrics[i].Name < metrics[j].Name })
+	return metrics
+}
+
+// launch 启动任务的调度循环，调用方需持有锁
+func (s *Scheduler) launch(ctx context.Context, state *taskState) {
+	s.running.Add(1)
+	go func() {
+		defer s.running.Done()
+		for {
+			delay := s.nextDelay(state.task.Interval)
+			next := s.now().Add(delay)
+			s.mu.Lock()
+			state.metrics.NextRunAt = &next
+			s.mu.Unlock()
+
+			timer := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+				s.runTask(ctx, state)
+			}
+		}
+	}()
+}
+
+// runTask 执行一次任务并记录指标，任务panic时记为失败，不影响后续调度
+func (s *Scheduler) runTask(ctx context.Context, state *taskState) {
+	if !s.acquire(ctx, state) {
+		s.mu.Lock()
+		state.metrics.Skipped++
+		state.metrics.Status = StatusSkipped
+		s.mu.Unlock()
+		return
+	}
+
+	started := s.now()
+	s.mu.Lock()
+	state.running = true
+	state.metrics.Status = StatusRunning
+	state.metrics.LastRunAt = &started
+	s.mu.Unlock()
+
+	removed, err := s.safeRun(ctx, state.task)
+	duration := s.now().Sub(started)
+
+	s.mu.Lock()
+	state.running = false
+	metrics := &state.metrics
+	metrics.Runs++
+	metrics.LastDuration = duration.String()
+	metrics.LastRemoved = removed
+	metrics.Removed += removed
+	if err != nil {
+		metrics.Failures++
+		metrics.LastError = err.Error()
+		metrics.Status = StatusFailed
+	} else {
+		metrics.LastError = ""
+		metrics.Status = StatusOK
+	}
+	s.mu.Unlock()
+
+	if err != nil {
+		s.logger.Warn("Maintenance task failed",
+			zap.String("task", state.task.Name),
+			zap.Int64("removed", removed),
+			zap.Duration("duration", duration),
+			zap.Error(err))
+		return
+	}
+	if removed > 0 {
+		s.logger.Info("Maintenance task finished",
+			zap.String("task", state.task.Name),
+			zap.Int64("removed", removed),
+			zap.Duration("duration", duration))
+	}
+}
+
+// acquire 获取任务锁，返回false表示本次由其他实例执行
+//
+// 锁的有效期为最短等待间隔，下一次调度时任一实例都能重新获取；获取锁出错时照常执行
+func (s *Scheduler) acquire(ctx context.Context, state *taskState) bool {
+	if s.locker == nil || state.task.Local {
+		return true
+	}
+	ttl := time.Duration(float64(state.task.Interval) * (1 - s.options.Jitter))
+	acquired, err := s.locker.TryLock(ctx, state.task.Name, ttl)
+	if err != nil {
+		s.logger.Warn("Failed to acquire maintenance task lock, running anyway",
+			zap.String("task", state.task.Name),
+			zap.Error(err))
+		return true
+	}
+	return acquired
+}
+
+// safeRun 执行任务并将panic转换为错误
+func (s *Scheduler) safeRun(ctx context.Context, task Task) (removed int64, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("维护任务panic: %v", r)
+		}
+	}()
+	return task.Run(ctx)
+}
+
+// nextDelay 计算带随机浮动的等待时间
+func (s *Scheduler) nextDelay(interval time.Duration) time.Duration {
+	factor := 1 + s.options.Jitter*(2*s.random()-1)
+	return time.Duration(float64(interval) * factor)
+}
+
+var (
+	defaultMu        sync.RWMutex
+	defaultScheduler *Scheduler
+)
+
+// SetDefault 设置全局维护任务调度器，启动时由main调用
+func SetDefault(scheduler *Scheduler) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultScheduler = scheduler
+}
+
+// Default 返回全局维护任务调度器，未启用时返回nil
+func Default() *Scheduler {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultScheduler
+}
@@ -0,0 +1,238 @@
+package maintenance
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// stubSessions 分批删除会话的模拟仓储，共有 total 条过期会话
+type stubSessions struct {
+	total  int64
+	calls  int
+	limits []int
+}
+
+func (s *stubSessions) DeleteExpiredSessions(ctx context.Context, before time.Time, limit int) (int64, error) {
+	s.calls++
+	s.limits = append(s.limits, limit)
+	n := int64(limit)
+	if s.total < n {
+		n = s.total
+	}
+	s.total -= n
+	return n, nil
+}
+
+// stubPruner 进程内存储的模拟清理
+type stubPruner struct {
+	removed int
+}
+
+func (p stubPruner) PruneExpired() int {
+	return p.removed
+}
+
+func TestScheduler_RunTaskRecordsMetrics(t *testing.T) {
+	scheduler := NewScheduler(Options{}, nil)
+	assert.Equal(t, defaultBatchSize, scheduler.BatchSize())
+
+	fail := true
+	require.NoError(t, scheduler.Register(Task{Name: "codes", Run: func(context.Context) (int64, error) {
+		if fail {
+			return 2, errors.New("db down")
+		}
+		return 5, nil
+	}}))
+	require.NoError(t, scheduler.Register(Task{Name: "broken", Run: func(context.Context) (int64, error) {
+		panic("boom")
+	}}))
+	assert.Error(t, scheduler.Register(Task{Name: "codes", Run: func(context.Context) (int64, error) { return 0, nil }}))
+
+	ctx := context.Background()
+	scheduler.runTask(ctx, scheduler.tasks["codes"])
+	fail = false
+	scheduler.runTask(ctx, scheduler.tasks["codes"])
+	scheduler.runTask(ctx, scheduler.tasks["broken"])
+
+	metrics := scheduler.Metrics()
+	require.Len(t, metrics, 2)
+	assert.Equal(t, "broken", metrics[0].Name)
+	assert.Equal(t, int64(1), metrics[0].Failures)
+	assert.Contains(t, metrics[0].LastError, "boom")
+
+	assert.Equal(t, StatusFailed, metrics[0].Status)
+	assert.False(t, metrics[0].Healthy)
+
+	codes := metrics[1]
+	assert.Equal(t, StatusOK, codes.Status)
+	assert.True(t, codes.Healthy)
+	assert.Equal(t, int64(2), codes.Runs)
+	assert.Equal(t, int64(1), codes.Failures)
+	assert.Equal(t, int64(7), codes.Removed)
+	assert.Equal(t, int64(5), codes.LastRemoved)
+	assert.Empty(t, codes.LastError, "成功后清空失败原因")
+	assert.NotNil(t, codes.LastRunAt)
+	assert.Equal(t, time.Hour.String(), codes.Interval)
+}
+
+// stubLocker 记录获取锁请求的任务锁
+type stubLocker struct {
+	acquired bool
+	err      error
+	names    []string
+	ttls     []time.Duration
+}
+
+func (l *stubLocker) TryLock(_ context.Context, name string, ttl time.Duration) (bool, error) {
+	l.names = append(l.names, name)
+	l.ttls = append(l.ttls, ttl)
+	return l.acquired, l.err
+}
+
+func TestScheduler_Locker(t *testing.T) {
+	scheduler := NewScheduler(Options{Interval: time.Hour, Jitter: 0.1}, nil)
+	locker := &stubLocker{}
+	scheduler.SetLocker(locker)
+
+	var shared, local int
+	require.NoError(t, scheduler.Register(Task{Name: "shared", Run: func(context.Context) (int64, error) {
+		shared++
+		return 0, nil
+	}}))
+	require.NoError(t, scheduler.Register(Task{Name: "local", Local: true, Run: func(context.Context) (int64, error) {
+		local++
+		return 0, nil
+	}}))
+
+	ctx := context.Background()
+	scheduler.runTask(ctx, scheduler.tasks["shared"])
+	scheduler.runTask(ctx, scheduler.tasks["local"])
+	assert.Equal(t, 0, shared, "其他实例持有锁时跳过")
+	assert.Equal(t, 1, local, "本地任务不加锁")
+	assert.Equal(t, []string{"shared"}, locker.names)
+	assert.Equal(t, []time.Duration{54 * time.Minute}, locker.ttls, "锁在最短等待间隔后过期")
+
+	metrics := scheduler.Metrics()
+	assert.Equal(t, StatusOK, metrics[0].Status)
+	assert.Equal(t, StatusSkipped, metrics[1].Status)
+	assert.Equal(t, int64(1), metrics[1].Skipped)
+	assert.True(t, metrics[1].Healthy)
+
+	// 获取锁出错时照常执行
+	locker.err = errors.New("redis down")
+	scheduler.runTask(ctx, scheduler.tasks["shared"])
+	assert.Equal(t, 1, shared)
+}
+
+func TestScheduler_StartWarnsWithoutLocker(t *testing.T) {
+	core, logs := observer.New(zapcore.WarnLevel)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	NewScheduler(Options{Interval: time.Hour}, zap.New(core)).Start(ctx)
+	assert.Equal(t, 1, logs.FilterMessageSnippet("without distributed lock").Len())
+
+	locked := NewScheduler(Options{Interval: time.Hour}, zap.New(core))
+	locked.SetLocker(&stubLocker{})
+	locked.Start(ctx)
+	assert.Equal(t, 1, logs.Len(), "设置锁后不警告")
+}
+
+func TestScheduler_ConfiguredIntervals(t *testing.T) {
+	scheduler := NewScheduler(Options{
+		Interval:  time.Hour,
+		Intervals: map[string]time.Duration{"codes": 15 * time.Minute, "backup": 12 * time.Hour},
+	}, nil)
+	run := func(context.Context) (int64, error) { return 0, nil }
+	require.NoError(t, scheduler.Register(Task{Name: "codes", Run: run}))
+	require.NoError(t, scheduler.Register(Task{Name: "backup", Interval: 24 * time.Hour, Run: run}))
+	require.NoError(t, scheduler.Register(Task{Name: "shares", Run: run}))
+
+	assert.Equal(t, 15*time.Minute, scheduler.tasks["codes"].task.Interval)
+	assert.Equal(t, 12*time.Hour, scheduler.tasks["backup"].task.Interval, "配置优先于任务自带的间隔")
+	assert.Equal(t, time.Hour, scheduler.tasks["shares"].task.Interval)
+}
+
+func TestScheduler_NextDelayJitter(t *testing.T) {
+	scheduler := NewScheduler(Options{Interval: time.Hour, Jitter: 0.2}, nil)
+
+	scheduler.random = func() float64 { return 0 }
+	assert.Equal(t, 48*time.Minute, scheduler.nextDelay(time.Hour))
+	scheduler.random = func() float64 { return 0.5 }
+	assert.Equal(t, time.Hour, scheduler.nextDelay(time.Hour))
+	scheduler.random = func() float64 { return 1 }
+	assert.Equal(t, 72*time.Minute, scheduler.nextDelay(time.Hour))
+}
+
+func TestScheduler_StartRunsRegisteredTasks(t *testing.T) {
+	scheduler := NewScheduler(Options{Interval: 10 * time.Millisecond}, nil)
+	var early, late atomic.Int32
+	require.NoError(t, scheduler.Register(Task{Name: "early", Run: func(context.Context) (int64, error) {
+		early.Add(1)
+		return 1, nil
+	}}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	scheduler.Start(ctx)
+	require.NoError(t, scheduler.Register(Task{Name: "late", Run: func(context.Context) (int64, error) {
+		late.Add(1)
+		return 0, nil
+	}}))
+
+	assert.Eventually(t, func() bool { return early.Load() >= 2 && late.Load() >= 1 }, time.Second, 5*time.Millisecond)
+	cancel()
+	scheduler.Wait()
+
+	for _, metrics := range scheduler.Metrics() {
+		assert.NotNil(t, metrics.NextRunAt, metrics.Name)
+	}
+}
+
+func TestRegisterCleanupTasks(t *testing.T) {
+	scheduler := NewScheduler(Options{BatchSize: 100}, nil)
+	sessions := &stubSessions{total: 250}
+	require.NoError(t, RegisterCleanupTasks(scheduler, Sources{
+		Sessions: sessions,
+		Tokens:   []Pruner{stubPruner{removed: 2}, nil, stubPruner{removed: 3}},
+	}))
+
+	names := make([]string, 0)
+	for _, metrics := range scheduler.Metrics() {
+		names = append(names, metrics.Name)
+	}
+	assert.Equal(t, []string{TaskRefreshTokens, TaskSessions}, names, "未提供的来源不注册任务")
+
+	ctx := context.Background()
+	removed, err := scheduler.tasks[TaskSessions].task.Run(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(250), removed)
+	assert.Equal(t, 3, sessions.calls, "不足一批时停止")
+	assert.Equal(t, []int{100, 100, 100}, sessions.limits)
+
+	removed, err = scheduler.tasks[TaskRefreshTokens].task.Run(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), removed)
+}
+
+func TestDrainStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	removed, err := drain(ctx, 10, func(limit int) (int64, error) {
+		calls++
+		cancel()
+		return int64(limit), nil
+	})
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, int64(10), removed)
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,129 @@
+package maintenance
+
+import (
+	"context"
+	"time"
+)
+
+// 清理任务名称
+const (
+	TaskVerificationCodes   = "verification_codes"   // 过期验证码
+	TaskSessions            = "sessions"             // 过期登录会话
+	TaskExpiredShares       = "expired_shares"       // 已过期但仍为有效状态的分享
+	TaskRefreshTokens       = "refresh_tokens"       // 进程内刷新令牌登记和吊销记录
+	TaskRateLimits          = "rate_limits"          // 进程内限流和计数器窗口
+	TaskRequestLimits       = "request_limits"       // 进程内接口请求限流令牌桶
+	TaskTrash               = "trash"                // 超过保留期的回收站项目
+	TaskBackup              = "backup"               // 关键表元数据备份
+	TaskFolderRuleLogs      = "folder_rule_logs"     // 超过保留期的文件夹规则执行日志
+	TaskStorageReservations = "storage_reservations" // 过期未提交的上传存储空间预留
+	TaskUploadChunks        = "upload_chunks"        // 过期未合并的上传分片
+	TaskRetainedContent     = "retained_content"     // 超过保留期的已删除文件存储对象
+	TaskArchivedTeams       = "archived_teams"       // 超过恢复期限的已删除团队
+	TaskFileTransfers       = "file_transfers"       // 超过有效期未接受的文件所有权转移
+	TaskInactiveAccounts    = "inactive_accounts"    // 长期未登录账户的提醒和处置
+)
+
+// CodeCleaner 清理过期验证码，由验证码服务实现
+type CodeCleaner interface {
+	CleanupExpiredCodes(ctx context.Context) (int64, error)
+}
+
+// SessionCleaner 分批删除过期会话，由用户仓储实现
+type SessionCleaner interface {
+	DeleteExpiredSessions(ctx context.Context, before time.Time, limit int) (int64, error)
+}
+
+// ShareExpirer 分批标记过期分享，由分享仓储实现
+type ShareExpirer interface {
+	ExpireShares(ctx context.Context, now time.Time, limit int) (int64, error)
+}
+
+// Pruner 进程内存储的过期数据清理，由内存令牌存储、内存限流器等实现
+//
+// Redis存储依靠键过期自动清理，不实现该接口
+type Pruner interface {
+	PruneExpired() int
+}
+
+// Sources 清理任务的数据来源，为nil的来源对应的任务不会注册
+type Sources struct {
+	Codes    CodeCleaner
+	Sessions SessionCleaner
+	Shares   ShareExpirer
+	Tokens   []Pruner // 进程内令牌存储
+}
+
+// RegisterCleanupTasks 向调度器注册全部过期数据清理任务
+//
+// 使用示例：
+//
+//	scheduler := maintenance.NewScheduler(maintenance.OptionsFromConfig(cfg), logger)
+//	maintenance.RegisterCleanupTasks(scheduler, maintenance.Sources{
+//		Codes:    verification.NewVerificationService(db, nil, nil, nil, logger),
+//		Sessions: userrepo.NewUserRepository(db),
+//		Shares:   filerepo.NewShareRepository(db),
+//	})
+func RegisterCleanupTasks(scheduler *Scheduler, sources Sources) error {
+	var tasks []Task
+	if sources.Codes != nil {
+		tasks = append(tasks, Task{Name: TaskVerificationCodes, Run: sources.Codes.CleanupExpiredCodes})
+	}
+	if sources.Sessions != nil {
+		tasks = append(tasks, Task{Name: TaskSessions, Run: func(ctx context.Context) (int64, error) {
+			return drain(ctx, scheduler.BatchSize(), func(limit int) (int64, error) {
+				return sources.Sessions.DeleteExpiredSessions(ctx, scheduler.now(), limit)
+			})
+		}})
+	}
+	if sources.Shares != nil {
+		tasks = append(tasks, Task{Name: TaskExpiredShares, Run: func(ctx context.Context) (int64, error) {
+			return drain(ctx, scheduler.BatchSize(), func(limit int) (int64, error) {
+				return sources.Shares.ExpireShares(ctx, scheduler.now(), limit)
+			})
+		}})
+	}
+	if len(sources.Tokens) > 0 {
+		tasks = append(tasks, PruneTask(TaskRefreshTokens, sources.Tokens...))
+	}
+
+	for _, task := range tasks {
+		if err := scheduler.Register(task); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PruneTask 创建清理进程内存储的任务，为nil的存储被忽略
+//
+// 进程内存储每个实例各自持有，任务在每个实例上都执行
+func PruneTask(name string, pruners ...Pruner) Task {
+	return Task{Name: name, Local: true, Run: func(context.Context) (int64, error) {
+		var removed int64
+		for _, pruner := range pruners {
+			if pruner != nil {
+				removed += int64(pruner.PruneExpired())
+			}
+		}
+		return removed, nil
+	}}
+}
+
+// drain 循环执行批量操作，直到某一批不足 limit 条或ctx取消，返回累计处理的记录数
+func drain(ctx context.Context, limit int, batch func(limit int) (int64, error)) (int64, error) {
+	var total int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+		n, err := batch(limit)
+		total += n
+		if err != nil {
+			return total, err
+		}
+		if n < int64(limit) {
+			return total, nil
+		}
+	}
+}
@@ -0,0 +1,124 @@
+// Package readonly 服务只读模式
+//
+// 数据库维护或迁移期间开启只读模式，修改数据的接口返回503，登录、浏览和下载保持可用
+package readonly
+
+import (
+	"context"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"cloudpan/internal/service/featureflag"
+)
+
+// FlagKey 开启只读模式的特性开关
+//
+// 按匿名用户评估，需同时启用并设为默认开启；可用生效时间窗口预约维护时段
+const FlagKey = "read_only_mode"
+
+// 只读模式的开启来源
+const (
+	SourceConfig      = "config"       // 配置文件或环境变量 server.read_only
+	SourceFeatureFlag = "feature_flag" // 特性开关 read_only_mode
+)
+
+// FlagEvaluator 评估特性开关，由 featureflag.FeatureFlagService 实现
+type FlagEvaluator interface {
+	Evaluate(ctx context.Context, key string, userID uint) (*featureflag.Evaluation, error)
+}
+
+// Status 只读模式状态
+type Status struct {
+	ReadOnly bool   `json:"read_only"`        // 是否处于只读模式
+	Source   string `json:"source,omitempty"` // 开启来源：config、feature_flag
+}
+
+// Mode 只读模式开关
+//
+// 配置开启时始终只读；否则按特性开关判断，特性开关评估失败(如数据库维护中)时沿用上一次的结果，
+// 避免维护期间因读取不到开关而意外恢复写入
+//
+// 使用示例：
+//
+//	mode := readonly.NewMode(config.AppConfig.Server.ReadOnly, featureFlagService, logger)
+//	if mode.ReadOnly(ctx) { ... }
+type Mode struct {
+	static bool
+	flags  FlagEvaluator
+	logger *zap.Logger
+
+	mu       sync.Mutex
+	lastFlag bool
+}
+
+// NewMode 创建只读模式开关，flags为nil时只使用配置
+func NewMode(static bool, flags FlagEvaluator, logger *zap.Logger) *Mode {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &Mode{
+		static: static,
+		flags:  flags,
+		logger: logger,
+	}
+}
+
+// ReadOnly 判断当前是否处于只读模式
+func (m *Mode) ReadOnly(ctx context.Context) bool {
+	return m.Status(ctx).ReadOnly
+}
+
+// Status 返回只读模式状态及开启来源
+func (m *Mode) Status(ctx context.Context) Status {
+	if m.static {
+		return Status{ReadOnly: true, Source: SourceConfig}
+	}
+	if m.flags == nil {
+		return Status{}
+	}
+
+	enabled := m.evaluateFlag(ctx)
+	if !enabled {
+		return Status{}
+	}
+	return Status{ReadOnly: true, Source: SourceFeatureFlag}
+}
+
+// evaluateFlag 评估特性开关并记录结果，评估失败时返回上一次的结果
+func (m *Mode) evaluateFlag(ctx context.Context) bool {
+	evaluation, err := m.flags.Evaluate(ctx, FlagKey, 0)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err != nil {
+		m.logger.Warn("Failed to evaluate read-only flag, keeping last state",
+			zap.Bool("read_only", m.lastFlag),
+			zap.Error(err))
+		return m.lastFlag
+	}
+	if evaluation.Enabled != m.lastFlag {
+		m.logger.Warn("Read-only mode changed by feature flag", zap.Bool("read_only", evaluation.Enabled))
+	}
+	m.lastFlag = evaluation.Enabled
+	return m.lastFlag
+}
+
+var (
+	defaultMu   sync.RWMutex
+	defaultMode *Mode
+)
+
+// SetDefault 设置全局只读模式开关，启动时由main调用
+func SetDefault(mode *Mode) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultMode = mode
+}
+
+// Default 返回全局只读模式开关，未设置时返回nil
+func Default() *Mode {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultMode
+}
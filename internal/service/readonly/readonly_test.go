@@ -0,0 +1,54 @@
+package readonly
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"cloudpan/internal/service/featureflag"
+)
+
+// stubFlags 返回固定结果的特性开关
+type stubFlags struct {
+	enabled bool
+	err     error
+	keys    []string
+}
+
+func (s *stubFlags) Evaluate(_ context.Context, key string, userID uint) (*featureflag.Evaluation, error) {
+	s.keys = append(s.keys, key)
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &featureflag.Evaluation{Key: key, Enabled: s.enabled}, nil
+}
+
+func TestMode_Config(t *testing.T) {
+	flags := &stubFlags{}
+	mode := NewMode(true, flags, nil)
+
+	assert.Equal(t, Status{ReadOnly: true, Source: SourceConfig}, mode.Status(context.Background()))
+	assert.Empty(t, flags.keys, "配置开启时不评估特性开关")
+	assert.False(t, NewMode(false, nil, nil).ReadOnly(context.Background()))
+}
+
+func TestMode_FeatureFlag(t *testing.T) {
+	ctx := context.Background()
+	flags := &stubFlags{}
+	mode := NewMode(false, flags, nil)
+
+	assert.False(t, mode.ReadOnly(ctx))
+	assert.Equal(t, []string{FlagKey}, flags.keys)
+
+	flags.enabled = true
+	assert.Equal(t, Status{ReadOnly: true, Source: SourceFeatureFlag}, mode.Status(ctx))
+
+	// 数据库维护时读取不到特性开关，保持只读
+	flags.err = errors.New("database unavailable")
+	assert.True(t, mode.ReadOnly(ctx))
+
+	flags.err, flags.enabled = nil, false
+	assert.False(t, mode.ReadOnly(ctx))
+}
@@ -0,0 +1,452 @@
+package share
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/cache"
+	"cloudpan/internal/pkg/captcha"
+	"cloudpan/internal/pkg/clock"
+	"cloudpan/internal/pkg/config"
+	dbmodels "cloudpan/internal/pkg/database/models"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/ratelimit"
+	"cloudpan/internal/repository/models"
+)
+
+// 举报审核操作
+const (
+	AbuseActionDismiss = "dismiss" // 驳回：未发现违规，被暂停的分享恢复有效
+	AbuseActionDisable = "disable" // 确认违规：停用分享
+)
+
+// MaxAbuseDetailsLength 举报补充说明的最大长度(字符数)
+const MaxAbuseDetailsLength = 1000
+
+// MaxAbuseReviewNoteLength 审核备注的最大长度(字符数)
+const MaxAbuseReviewNoteLength = 500
+
+// AbuseService 公开分享举报服务接口
+//
+// 访问者无需登录即可按原因类别举报公开分享，同一IP的举报按窗口限流，可选要求人机验证；
+// 同一IP对同一分享只能有一条待审核举报。分享收到第一条待审核举报时提醒全部管理员，
+// 待审核举报来自的不同IP数达到阈值时自动暂停分享(访问返回分享已失效)并通知分享者和管理员。
+// 管理员在审核队列中处理举报，同一分享的待审核举报一起处理：驳回时恢复被暂停的分享，
+// 确认违规时停用分享，处理结果通知分享者
+//
+// 使用示例：
+//
+//	service := NewAbuseService(reportRepo, shareRepo, userRepo, notificationRepo, metadataService, limiter, nil, AbusePolicyFromConfig(cfg), nil, logger)
+//	report, err := service.Report(ctx, &AbuseReportRequest{Code: code, Reason: models.ShareAbuseReasonPhishing, ClientIP: c.ClientIP()})
+//	reports, total, err := service.ListReports(ctx, models.ShareAbuseStatusPending, 0, 1, 20)
+//	resolution, err := service.Resolve(ctx, adminID, reportID, AbuseActionDisable, "钓鱼页面")
+type AbuseService interface {
+	Report(ctx context.Context, req *AbuseReportRequest) (*models.ShareAbuseReport, error)
+	ListReports(ctx context.Context, status string, shareID uint, page, pageSize int) ([]*models.ShareAbuseReport, int64, error)
+	Resolve(ctx context.Context, adminID, reportID uint, action, note string) (*AbuseResolution, error)
+}
+
+// AbuseReportStore 分享举报数据访问，由举报仓储实现
+type AbuseReportStore interface {
+	Create(ctx context.Context, report *models.ShareAbuseReport) error
+	GetByID(ctx context.Context, id uint) (*models.ShareAbuseReport, error)
+	HasPending(ctx context.Context, shareID uint, reporterIP string) (bool, error)
+	CountPendingReporters(ctx context.Context, shareID uint) (int64, error)
+	List(ctx context.Context, status string, shareID uint, limit, offset int) ([]*models.ShareAbuseReport, int64, error)
+	ResolvePending(ctx context.Context, shareID uint, status string, reviewedBy uint, note *string, reviewedAt time.Time) (int64, error)
+}
+
+// AbuseShareStore 举报处理使用的分享数据访问，由分享仓储实现
+type AbuseShareStore interface {
+	GetByID(ctx context.Context, id uint) (*models.FileShare, error)
+	GetByCode(ctx context.Context, code string) (*models.FileShare, error)
+	TransitionStatus(ctx context.Context, id uint, from []string, to string) (bool, error)
+}
+
+// AdminLister 查询需要接收举报提醒的管理员，由用户仓储实现
+type AdminLister interface {
+	ListActiveUserIDsByRole(ctx context.Context, roleName string) ([]uint, error)
+}
+
+// AbuseReportRequest 举报请求
+type AbuseReportRequest struct {
+	Code         string // 分享码
+	Reason       string // 原因类别，见 models.ShareAbuseReasons
+	Details      string // 补充说明
+	CaptchaToken string // 人机验证令牌，未要求人机验证时忽略
+	ClientIP     string // 举报人IP
+	ReporterID   *uint  // 已登录的举报人ID
+}
+
+// AbuseResolution 举报审核结果
+type AbuseResolution struct {
+	ShareID     uint   `json:"share_id"`     // 分享ID
+	ShareStatus string `json:"share_status"` // 处理后的分享状态，分享已删除时为空
+	Resolved    int64  `json:"resolved"`     // 一起处理的待审核举报数
+}
+
+// AbusePolicy 分享举报策略
+type AbusePolicy struct {
+	ReportsPerWindow int           // 同一IP每个窗口内允许提交的举报数
+	ReportWindow     time.Duration // 举报计数窗口
+	SuspendThreshold int           // 待审核举报来自多少个不同IP时自动暂停分享，0表示不自动暂停
+}
+
+// DefaultAbusePolicy 默认分享举报策略
+func DefaultAbusePolicy() AbusePolicy {
+	return AbusePolicy{
+		ReportsPerWindow: 5,
+		ReportWindow:     time.Hour,
+		SuspendThreshold: 3,
+	}
+}
+
+// AbusePolicyFromConfig 从分享配置生成举报策略，未配置的限流项使用默认值，自动暂停阈值为0时不自动暂停
+func AbusePolicyFromConfig(cfg config.ShareConfig) AbusePolicy {
+	policy := DefaultAbusePolicy()
+	if cfg.AbuseReportsPerWindow > 0 {
+		policy.ReportsPerWindow = cfg.AbuseReportsPerWindow
+	}
+	if cfg.AbuseReportWindow > 0 {
+		policy.ReportWindow = cfg.AbuseReportWindow
+	}
+	policy.SuspendThreshold = max(cfg.AbuseSuspendThreshold, 0)
+	return policy
+}
+
+// abuseService 公开分享举报服务实现
+type abuseService struct {
+	reports  AbuseReportStore
+	shares   AbuseShareStore
+	admins   AdminLister
+	notifier NotificationWriter
+	metadata MetadataInvalidator
+	limiter  ratelimit.Limiter
+	verifier captcha.Verifier
+	policy   AbusePolicy
+	logger   *zap.Logger
+	clock    clock.Clock
+}
+
+// NewAbuseService 创建公开分享举报服务
+//
+// admins 或 notifier 为nil时不提醒管理员，notifier 为nil时也不通知分享者；metadata 可以为nil，
+// 此时暂停和停用分享后不清除公开元数据缓存；verifier 为nil时举报不需要人机验证；clk为nil时使用系统时钟
+func NewAbuseService(reports AbuseReportStore, shares AbuseShareStore, admins AdminLister, notifier NotificationWriter, metadata MetadataInvalidator, limiter ratelimit.Limiter, verifier captcha.Verifier, policy AbusePolicy, clk clock.Clock, logger *zap.Logger) AbuseService {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &abuseService{
+		reports:  reports,
+		shares:   shares,
+		admins:   admins,
+		notifier: notifier,
+		metadata: metadata,
+		limiter:  limiter,
+		verifier: verifier,
+		policy:   policy,
+		logger:   logger,
+		clock:    clock.OrReal(clk),
+	}
+}
+
+// Report 举报公开分享
+//
+// 检查顺序：参数 -> 举报频率 -> 人机验证 -> 分享有效(含已暂停) -> 没有同一IP的待审核举报。
+// 人机验证未提交或不通过时返回 captcha.ErrRequired、captcha.ErrInvalid
+func (s *abuseService) Report(ctx context.Context, req *AbuseReportRequest) (*models.ShareAbuseReport, error) {
+	if req == nil || req.Code == "" {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "分享码不能为空")
+	}
+	if !isAbuseReason(req.Reason) {
+		return nil, pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "举报原因必须是 %s 之一", strings.Join(models.ShareAbuseReasons, "、"))
+	}
+	details := strings.TrimSpace(req.Details)
+	if utf8.RuneCountInString(details) > MaxAbuseDetailsLength {
+		return nil, pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "补充说明不能超过%d个字符", MaxAbuseDetailsLength)
+	}
+
+	limitKey := cache.Keys.RateLimit(req.ClientIP, "share_report")
+	result, err := s.limiter.Allow(ctx, limitKey, s.policy.ReportsPerWindow, s.policy.ReportWindow)
+	if err != nil {
+		// 限流器不可用时仍由同一IP对同一分享只能有一条待审核举报兜底
+		s.logger.Warn("分享举报限流检查失败", zap.String("ip", req.ClientIP), zap.Error(err))
+	} else if !result.Allowed {
+		return nil, pkgErrors.WrapErrorf(pkgErrors.ErrTooManyRequests, "举报过于频繁，请%d分钟后再试", ceilUnits(result.RetryAfter, time.Minute))
+	}
+
+	if s.verifier != nil {
+		if err := s.verifier.Verify(ctx, req.CaptchaToken, req.ClientIP); err != nil {
+			if errors.Is(err, captcha.ErrRequired) || errors.Is(err, captcha.ErrInvalid) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("人机验证服务不可用: %w", err)
+		}
+	}
+
+	share, err := s.loadShare(ctx, func() (*models.FileShare, error) { return s.shares.GetByCode(ctx, req.Code) })
+	if err != nil {
+		return nil, err
+	}
+	if share.Status != models.ShareStatusActive && share.Status != models.ShareStatusSuspended {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "分享已失效")
+	}
+
+	duplicate, err := s.reports.HasPending(ctx, share.ID, req.ClientIP)
+	if err != nil {
+		return nil, pkgErrors.WrapError(err, "查询分享举报失败")
+	}
+	if duplicate {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrResourceExists, "您已举报过该分享，请等待管理员审核")
+	}
+
+	report := &models.ShareAbuseReport{
+		ShareID:    share.ID,
+		SharerID:   share.SharerID,
+		ShareCode:  share.ShareCode,
+		Reason:     req.Reason,
+		ReporterID: req.ReporterID,
+		ReporterIP: req.ClientIP,
+		Status:     models.ShareAbuseStatusPending,
+	}
+	if details != "" {
+		report.Details = &details
+	}
+	if err := s.reports.Create(ctx, report); err != nil {
+		return nil, pkgErrors.WrapError(err, "保存分享举报失败")
+	}
+	s.logger.Warn("分享被举报",
+		zap.Uint("share_id", share.ID),
+		zap.Uint("report_id", report.ID),
+		zap.String("reason", report.Reason),
+		zap.String("ip", req.ClientIP))
+
+	s.afterReport(ctx, share, report)
+	return report, nil
+}
+
+// afterReport 统计分享的待审核举报，提醒管理员并在达到阈值时暂停分享，失败只记录日志
+func (s *abuseService) afterReport(ctx context.Context, share *models.FileShare, report *models.ShareAbuseReport) {
+	reporters, err := s.reports.CountPendingReporters(ctx, share.ID)
+	if err != nil {
+		s.logger.Error("统计分享待审核举报失败", zap.Uint("share_id", share.ID), zap.Error(err))
+		return
+	}
+
+	if reporters == 1 {
+		s.notifyAdmins(ctx, share, "分享收到举报", fmt.Sprintf("分享 %s 被举报(原因：%s)，请在举报审核队列中处理。", share.ShareCode, report.Reason), reporters)
+	}
+	if s.policy.SuspendThreshold <= 0 || reporters < int64(s.policy.SuspendThreshold) || share.Status != models.ShareStatusActive {
+		return
+	}
+
+	suspended, err := s.shares.TransitionStatus(ctx, share.ID, []string{models.ShareStatusActive}, models.ShareStatusSuspended)
+	if err != nil {
+		s.logger.Error("暂停被举报的分享失败", zap.Uint("share_id", share.ID), zap.Error(err))
+		return
+	}
+	if !suspended {
+		return
+	}
+	s.invalidateMetadata(ctx, share)
+	s.logger.Warn("分享举报达到阈值，已暂停等待审核",
+		zap.Uint("share_id", share.ID),
+		zap.Int64("reporters", reporters))
+
+	s.notifyAdmins(ctx, share, "分享已因举报自动暂停", fmt.Sprintf("分享 %s 已收到来自 %d 个IP的举报，已自动暂停，请尽快审核。", share.ShareCode, reporters), reporters)
+	s.notifySharer(ctx, share, "分享链接已暂停", fmt.Sprintf("您的分享 %s 收到多次举报，已暂停访问等待管理员审核。", share.ShareCode))
+}
+
+// ListReports 分页查询举报，status为空时查询全部状态，shareID为0时查询全部分享
+func (s *abuseService) ListReports(ctx context.Context, status string, shareID uint, page, pageSize int) ([]*models.ShareAbuseReport, int64, error) {
+	switch status {
+	case "", models.ShareAbuseStatusPending, models.ShareAbuseStatusDismissed, models.ShareAbuseStatusActioned:
+	default:
+		return nil, 0, pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "举报状态无效")
+	}
+	if page < 1 || pageSize < 1 {
+		return nil, 0, pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "分页参数无效")
+	}
+
+	reports, total, err := s.reports.List(ctx, status, shareID, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, 0, pkgErrors.WrapError(err, "查询分享举报失败")
+	}
+	return reports, total, nil
+}
+
+// Resolve 审核举报，同一分享的全部待审核举报一起处理
+//
+// 先更新分享状态再标记举报，标记失败时重试不会重复改变分享状态；分享已删除时只标记举报
+func (s *abuseService) Resolve(ctx context.Context, adminID, reportID uint, action, note string) (*AbuseResolution, error) {
+	var status string
+	switch action {
+	case AbuseActionDismiss:
+		status = models.ShareAbuseStatusDismissed
+	case AbuseActionDisable:
+		status = models.ShareAbuseStatusActioned
+	default:
+		return nil, pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "审核操作必须是 %s 或 %s", AbuseActionDismiss, AbuseActionDisable)
+	}
+	note = strings.TrimSpace(note)
+	if utf8.RuneCountInString(note) > MaxAbuseReviewNoteLength {
+		return nil, pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "审核备注不能超过%d个字符", MaxAbuseReviewNoteLength)
+	}
+
+	report, err := s.reports.GetByID(ctx, reportID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "举报不存在")
+		}
+		return nil, pkgErrors.WrapError(err, "查询分享举报失败")
+	}
+	if report.Status != models.ShareAbuseStatusPending {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "举报已处理")
+	}
+
+	resolution := &AbuseResolution{ShareID: report.ShareID}
+	share, err := s.loadShare(ctx, func() (*models.FileShare, error) { return s.shares.GetByID(ctx, report.ShareID) })
+	switch {
+	case err == nil:
+		resolution.ShareStatus, err = s.applyResolution(ctx, share, action)
+		if err != nil {
+			return nil, err
+		}
+	case !pkgErrors.IsNotFoundError(err):
+		return nil, err
+	}
+
+	var notePtr *string
+	if note != "" {
+		notePtr = &note
+	}
+	resolution.Resolved, err = s.reports.ResolvePending(ctx, report.ShareID, status, adminID, notePtr, s.clock.Now())
+	if err != nil {
+		return nil, pkgErrors.WrapError(err, "保存举报审核结果失败")
+	}
+
+	s.logger.Info("分享举报已审核",
+		zap.Uint("share_id", report.ShareID),
+		zap.Uint("admin_id", adminID),
+		zap.String("action", action),
+		zap.Int64("resolved", resolution.Resolved))
+	return resolution, nil
+}
+
+// applyResolution 按审核操作更新分享状态并通知分享者，返回处理后的分享状态
+func (s *abuseService) applyResolution(ctx context.Context, share *models.FileShare, action string) (string, error) {
+	from, to := []string{models.ShareStatusSuspended}, models.ShareStatusActive
+	if action == AbuseActionDisable {
+		from, to = []string{models.ShareStatusActive, models.ShareStatusSuspended}, models.ShareStatusDisabled
+	}
+
+	changed, err := s.shares.TransitionStatus(ctx, share.ID, from, to)
+	if err != nil {
+		return "", pkgErrors.WrapError(err, "更新分享状态失败")
+	}
+	if !changed {
+		// 分享已过期、被撤销或无需恢复，保持原状态
+		return share.Status, nil
+	}
+
+	s.invalidateMetadata(ctx, share)
+	if to == models.ShareStatusDisabled {
+		s.notifySharer(ctx, share, "分享链接已停用", fmt.Sprintf("您的分享 %s 经管理员审核确认违规，已被停用。", share.ShareCode))
+	} else {
+		s.notifySharer(ctx, share, "分享链接已恢复", fmt.Sprintf("您的分享 %s 经管理员审核未发现违规，已恢复访问。", share.ShareCode))
+	}
+	return to, nil
+}
+
+// notifyAdmins 向全部管理员发送举报提醒，失败只记录日志
+func (s *abuseService) notifyAdmins(ctx context.Context, share *models.FileShare, title, content string, reporters int64) {
+	if s.admins == nil || s.notifier == nil {
+		return
+	}
+
+	adminIDs, err := s.admins.ListActiveUserIDsByRole(ctx, models.RoleNameAdmin)
+	if err != nil {
+		s.logger.Error("查询管理员失败，无法发送举报提醒", zap.Uint("share_id", share.ID), zap.Error(err))
+		return
+	}
+	for _, adminID := range adminIDs {
+		shareID := share.ID
+		notification := &models.Notification{
+			UserID:      adminID,
+			Type:        models.NotificationTypeShareAbuse,
+			Title:       title,
+			Content:     content,
+			Priority:    models.NotificationPriorityHigh,
+			RelatedType: "file_share",
+			RelatedID:   &shareID,
+			Data: &dbmodels.JSONMap{
+				"share_code": share.ShareCode,
+				"reporters":  reporters,
+			},
+		}
+		if err := s.notifier.Create(ctx, notification); err != nil {
+			s.logger.Error("发送举报提醒失败", zap.Uint("share_id", share.ID), zap.Uint("admin_id", adminID), zap.Error(err))
+		}
+	}
+}
+
+// notifySharer 通知分享者分享被暂停或审核结果，失败只记录日志
+func (s *abuseService) notifySharer(ctx context.Context, share *models.FileShare, title, content string) {
+	if s.notifier == nil {
+		return
+	}
+
+	shareID := share.ID
+	notification := &models.Notification{
+		UserID:      share.SharerID,
+		Type:        models.NotificationTypeShareAbuse,
+		Title:       title,
+		Content:     content,
+		Priority:    models.NotificationPriorityHigh,
+		RelatedType: "file_share",
+		RelatedID:   &shareID,
+		Data:        &dbmodels.JSONMap{"share_code": share.ShareCode},
+	}
+	if err := s.notifier.Create(ctx, notification); err != nil {
+		s.logger.Error("发送分享举报处理通知失败", zap.Uint("share_id", share.ID), zap.Error(err))
+	}
+}
+
+// invalidateMetadata 使分享的公开元数据缓存失效，失败只记录日志
+func (s *abuseService) invalidateMetadata(ctx context.Context, share *models.FileShare) {
+	if s.metadata == nil {
+		return
+	}
+	if err := s.metadata.Invalidate(ctx, share.ShareCode); err != nil {
+		s.logger.Warn("清除分享元数据缓存失败", zap.Uint("share_id", share.ID), zap.Error(err))
+	}
+}
+
+// loadShare 查询分享并将记录不存在转换为统一错误
+func (s *abuseService) loadShare(ctx context.Context, load func() (*models.FileShare, error)) (*models.FileShare, error) {
+	share, err := load()
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, pkgErrors.ErrResourceNotFound) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "分享不存在")
+		}
+		return nil, pkgErrors.WrapError(err, "查询分享失败")
+	}
+	return share, nil
+}
+
+// isAbuseReason 检查举报原因类别是否有效
+func isAbuseReason(reason string) bool {
+	for _, candidate := range models.ShareAbuseReasons {
+		if candidate == reason {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,375 @@
+package share
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/captcha"
+	"cloudpan/internal/pkg/clock"
+	"cloudpan/internal/pkg/config"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/ratelimit"
+	"cloudpan/internal/repository/models"
+)
+
+// memoryAbuseReports 内存分享举报
+type memoryAbuseReports struct {
+	reports []*models.ShareAbuseReport
+}
+
+func (m *memoryAbuseReports) Create(ctx context.Context, report *models.ShareAbuseReport) error {
+	report.ID = uint(len(m.reports) + 1)
+	m.reports = append(m.reports, report)
+	return nil
+}
+
+func (m *memoryAbuseReports) GetByID(ctx context.Context, id uint) (*models.ShareAbuseReport, error) {
+	for _, report := range m.reports {
+		if report.ID == id {
+			copied := *report
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *memoryAbuseReports) HasPending(ctx context.Context, shareID uint, reporterIP string) (bool, error) {
+	for _, report := range m.reports {
+		if report.ShareID == shareID && report.Status == models.ShareAbuseStatusPending && report.ReporterIP == reporterIP {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *memoryAbuseReports) CountPendingReporters(ctx context.Context, shareID uint) (int64, error) {
+	ips := make(map[string]bool)
+	for _, report := range m.reports {
+		if report.ShareID == shareID && report.Status == models.ShareAbuseStatusPending {
+			ips[report.ReporterIP] = true
+		}
+	}
+	return int64(len(ips)), nil
+}
+
+func (m *memoryAbuseReports) List(ctx context.Context, status string, shareID uint, limit, offset int) ([]*models.ShareAbuseReport, int64, error) {
+	var matched []*models.ShareAbuseReport
+	for _, report := range m.reports {
+		if (status == "" || report.Status == status) && (shareID == 0 || report.ShareID == shareID) {
+			matched = append(matched, report)
+		}
+	}
+	total := int64(len(matched))
+	if offset >= len(matched) {
+		return nil, total, nil
+	}
+	return matched[offset:min(offset+limit, len(matched))], total, nil
+}
+
+func (m *memoryAbuseReports) ResolvePending(ctx context.Context, shareID uint, status string, reviewedBy uint, note *string, reviewedAt time.Time) (int64, error) {
+	var resolved int64
+	for _, report := range m.reports {
+		if report.ShareID == shareID && report.Status == models.ShareAbuseStatusPending {
+			report.Status, report.ReviewedBy, report.ReviewedAt, report.ReviewNote = status, &reviewedBy, &reviewedAt, note
+			resolved++
+		}
+	}
+	return resolved, nil
+}
+
+// memoryAbuseShares 内存分享，按分享码和ID查询
+type memoryAbuseShares struct {
+	share *models.FileShare
+}
+
+func (m *memoryAbuseShares) GetByID(ctx context.Context, id uint) (*models.FileShare, error) {
+	if m.share == nil || m.share.ID != id {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *m.share
+	return &copied, nil
+}
+
+func (m *memoryAbuseShares) GetByCode(ctx context.Context, code string) (*models.FileShare, error) {
+	if m.share == nil || m.share.ShareCode != code {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *m.share
+	return &copied, nil
+}
+
+func (m *memoryAbuseShares) TransitionStatus(ctx context.Context, id uint, from []string, to string) (bool, error) {
+	for _, status := range from {
+		if m.share.Status == status {
+			m.share.Status = to
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// staticAdmins 固定的管理员列表
+type staticAdmins []uint
+
+func (a staticAdmins) ListActiveUserIDsByRole(ctx context.Context, roleName string) ([]uint, error) {
+	return a, nil
+}
+
+// recordingNotifier 记录发送的通知
+type recordingNotifier struct {
+	notifications []*models.Notification
+}
+
+func (r *recordingNotifier) Create(ctx context.Context, notification *models.Notification) error {
+	r.notifications = append(r.notifications, notification)
+	return nil
+}
+
+// recipients 返回收到通知的用户ID
+func (r *recordingNotifier) recipients() []uint {
+	ids := make([]uint, 0, len(r.notifications))
+	for _, notification := range r.notifications {
+		ids = append(ids, notification.UserID)
+	}
+	return ids
+}
+
+// stubVerifier 只接受令牌 ok 的人机验证
+type stubVerifier struct{}
+
+func (stubVerifier) Verify(ctx context.Context, token, remoteIP string) error {
+	switch token {
+	case "":
+		return captcha.ErrRequired
+	case "ok":
+		return nil
+	default:
+		return captcha.ErrInvalid
+	}
+}
+
+type abuseFixture struct {
+	service     *abuseService
+	reports     *memoryAbuseReports
+	shares      *memoryAbuseShares
+	notifier    *recordingNotifier
+	invalidator *recordingInvalidator
+}
+
+func newAbuseFixture(policy AbusePolicy, verifier captcha.Verifier) *abuseFixture {
+	share := &models.FileShare{FileID: 1, SharerID: 7, ShareCode: "abc123", Status: models.ShareStatusActive}
+	share.ID = 3
+	f := &abuseFixture{
+		reports:     &memoryAbuseReports{},
+		shares:      &memoryAbuseShares{share: share},
+		notifier:    &recordingNotifier{},
+		invalidator: &recordingInvalidator{},
+	}
+	f.service = NewAbuseService(f.reports, f.shares, staticAdmins{1, 2}, f.notifier, f.invalidator,
+		ratelimit.NewMemoryLimiter(), verifier, policy, clock.NewFake(testNow), nil).(*abuseService)
+	return f
+}
+
+func (f *abuseFixture) report(ip string) (*models.ShareAbuseReport, error) {
+	return f.service.Report(context.Background(), &AbuseReportRequest{
+		Code:     "abc123",
+		Reason:   models.ShareAbuseReasonPhishing,
+		Details:  "  仿冒登录页面  ",
+		ClientIP: ip,
+	})
+}
+
+func TestAbuseService_Report(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("validation", func(t *testing.T) {
+		f := newAbuseFixture(DefaultAbusePolicy(), nil)
+		_, err := f.service.Report(ctx, &AbuseReportRequest{Code: "abc123", Reason: "boring", ClientIP: "1.1.1.1"})
+		assert.True(t, pkgErrors.IsValidationError(err))
+
+		_, err = f.service.Report(ctx, &AbuseReportRequest{Code: "missing", Reason: models.ShareAbuseReasonSpam, ClientIP: "1.1.1.1"})
+		assert.True(t, pkgErrors.IsNotFoundError(err))
+
+		f.shares.share.Status = models.ShareStatusDisabled
+		_, err = f.report("1.1.1.1")
+		assert.True(t, pkgErrors.IsNotFoundError(err), "已停用的分享不接受举报")
+	})
+
+	t.Run("files report and notifies admins once", func(t *testing.T) {
+		f := newAbuseFixture(DefaultAbusePolicy(), nil)
+		report, err := f.report("1.1.1.1")
+		require.NoError(t, err)
+		assert.Equal(t, uint(3), report.ShareID)
+		assert.Equal(t, uint(7), report.SharerID)
+		assert.Equal(t, models.ShareAbuseStatusPending, report.Status)
+		require.NotNil(t, report.Details)
+		assert.Equal(t, "仿冒登录页面", *report.Details)
+		assert.Equal(t, []uint{1, 2}, f.notifier.recipients())
+
+		_, err = f.report("1.1.1.1")
+		assert.ErrorIs(t, err, pkgErrors.ErrResourceExists, "同一IP只能有一条待审核举报")
+
+		_, err = f.report("2.2.2.2")
+		require.NoError(t, err)
+		assert.Len(t, f.notifier.notifications, 2, "后续举报不重复提醒")
+		assert.Equal(t, models.ShareStatusActive, f.shares.share.Status)
+	})
+
+	t.Run("rate limited per ip", func(t *testing.T) {
+		policy := DefaultAbusePolicy()
+		policy.ReportsPerWindow = 1
+		f := newAbuseFixture(policy, nil)
+		_, err := f.report("1.1.1.1")
+		require.NoError(t, err)
+		_, err = f.report("1.1.1.1")
+		assert.True(t, pkgErrors.IsRateLimitError(err))
+	})
+
+	t.Run("captcha", func(t *testing.T) {
+		f := newAbuseFixture(DefaultAbusePolicy(), stubVerifier{})
+		_, err := f.report("1.1.1.1")
+		assert.ErrorIs(t, err, captcha.ErrRequired)
+
+		req := &AbuseReportRequest{Code: "abc123", Reason: models.ShareAbuseReasonMalware, ClientIP: "1.1.1.1", CaptchaToken: "wrong"}
+		_, err = f.service.Report(ctx, req)
+		assert.ErrorIs(t, err, captcha.ErrInvalid)
+
+		req.CaptchaToken = "ok"
+		_, err = f.service.Report(ctx, req)
+		require.NoError(t, err)
+	})
+
+	t.Run("suspends at threshold of distinct reporters", func(t *testing.T) {
+		f := newAbuseFixture(DefaultAbusePolicy(), nil)
+		for _, ip := range []string{"1.1.1.1", "2.2.2.2"} {
+			_, err := f.report(ip)
+			require.NoError(t, err)
+		}
+		assert.Equal(t, models.ShareStatusActive, f.shares.share.Status)
+
+		_, err := f.report("3.3.3.3")
+		require.NoError(t, err)
+		assert.Equal(t, models.ShareStatusSuspended, f.shares.share.Status)
+		assert.Equal(t, []string{"abc123"}, f.invalidator.codes)
+		assert.Equal(t, []uint{1, 2, 1, 2, 7}, f.notifier.recipients(), "暂停时提醒管理员并通知分享者")
+
+		_, err = f.report("4.4.4.4")
+		require.NoError(t, err, "暂停中的分享仍可举报")
+		assert.Len(t, f.notifier.notifications, 5)
+	})
+
+	t.Run("threshold disabled", func(t *testing.T) {
+		f := newAbuseFixture(AbusePolicy{ReportsPerWindow: 5, ReportWindow: time.Hour}, nil)
+		for _, ip := range []string{"1.1.1.1", "2.2.2.2", "3.3.3.3", "4.4.4.4"} {
+			_, err := f.report(ip)
+			require.NoError(t, err)
+		}
+		assert.Equal(t, models.ShareStatusActive, f.shares.share.Status)
+	})
+}
+
+func TestAbuseService_Resolve(t *testing.T) {
+	ctx := context.Background()
+
+	suspendedFixture := func(t *testing.T) *abuseFixture {
+		f := newAbuseFixture(DefaultAbusePolicy(), nil)
+		for _, ip := range []string{"1.1.1.1", "2.2.2.2", "3.3.3.3"} {
+			_, err := f.report(ip)
+			require.NoError(t, err)
+		}
+		require.Equal(t, models.ShareStatusSuspended, f.shares.share.Status)
+		f.notifier.notifications = nil
+		f.invalidator.codes = nil
+		return f
+	}
+
+	t.Run("dismiss restores suspended share", func(t *testing.T) {
+		f := suspendedFixture(t)
+		resolution, err := f.service.Resolve(ctx, 1, 2, AbuseActionDismiss, "误报")
+		require.NoError(t, err)
+		assert.Equal(t, &AbuseResolution{ShareID: 3, ShareStatus: models.ShareStatusActive, Resolved: 3}, resolution)
+		assert.Equal(t, models.ShareStatusActive, f.shares.share.Status)
+		assert.Equal(t, []uint{7}, f.notifier.recipients())
+		assert.Equal(t, []string{"abc123"}, f.invalidator.codes)
+
+		for _, report := range f.reports.reports {
+			assert.Equal(t, models.ShareAbuseStatusDismissed, report.Status)
+			require.NotNil(t, report.ReviewedBy)
+			assert.Equal(t, uint(1), *report.ReviewedBy)
+			assert.Equal(t, testNow, *report.ReviewedAt)
+			assert.Equal(t, "误报", *report.ReviewNote)
+		}
+
+		_, err = f.service.Resolve(ctx, 1, 1, AbuseActionDisable, "")
+		assert.ErrorIs(t, err, pkgErrors.ErrOperationNotAllowed, "已处理的举报不能再次处理")
+	})
+
+	t.Run("disable confirms violation", func(t *testing.T) {
+		f := suspendedFixture(t)
+		resolution, err := f.service.Resolve(ctx, 1, 1, AbuseActionDisable, "")
+		require.NoError(t, err)
+		assert.Equal(t, models.ShareStatusDisabled, resolution.ShareStatus)
+		assert.Equal(t, models.ShareStatusDisabled, f.shares.share.Status)
+		assert.Equal(t, models.ShareAbuseStatusActioned, f.reports.reports[2].Status)
+		assert.Nil(t, f.reports.reports[2].ReviewNote)
+		assert.Equal(t, []uint{7}, f.notifier.recipients())
+	})
+
+	t.Run("dismiss keeps share revoked meanwhile", func(t *testing.T) {
+		f := suspendedFixture(t)
+		f.shares.share.Status = models.ShareStatusDisabled
+		resolution, err := f.service.Resolve(ctx, 1, 1, AbuseActionDismiss, "")
+		require.NoError(t, err)
+		assert.Equal(t, models.ShareStatusDisabled, resolution.ShareStatus)
+		assert.Empty(t, f.notifier.notifications)
+	})
+
+	t.Run("share deleted", func(t *testing.T) {
+		f := suspendedFixture(t)
+		f.shares.share.ID = 99
+		resolution, err := f.service.Resolve(ctx, 1, 1, AbuseActionDisable, "")
+		require.NoError(t, err)
+		assert.Equal(t, "", resolution.ShareStatus)
+		assert.Equal(t, int64(3), resolution.Resolved)
+	})
+
+	t.Run("invalid input", func(t *testing.T) {
+		f := newAbuseFixture(DefaultAbusePolicy(), nil)
+		_, err := f.service.Resolve(ctx, 1, 1, "ban", "")
+		assert.True(t, pkgErrors.IsValidationError(err))
+		_, err = f.service.Resolve(ctx, 1, 42, AbuseActionDismiss, "")
+		assert.True(t, pkgErrors.IsNotFoundError(err))
+	})
+}
+
+func TestAbuseService_ListReports(t *testing.T) {
+	ctx := context.Background()
+	f := newAbuseFixture(DefaultAbusePolicy(), nil)
+	for _, ip := range []string{"1.1.1.1", "2.2.2.2"} {
+		_, err := f.report(ip)
+		require.NoError(t, err)
+	}
+
+	reports, total, err := f.service.ListReports(ctx, models.ShareAbuseStatusPending, 0, 1, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	assert.Len(t, reports, 1)
+
+	_, _, err = f.service.ListReports(ctx, "closed", 0, 1, 20)
+	assert.True(t, pkgErrors.IsValidationError(err))
+}
+
+func TestAbusePolicyFromConfig(t *testing.T) {
+	policy := AbusePolicyFromConfig(config.ShareConfig{})
+	assert.Equal(t, 5, policy.ReportsPerWindow)
+	assert.Equal(t, time.Hour, policy.ReportWindow)
+	assert.Equal(t, 0, policy.SuspendThreshold, "未配置时不自动暂停")
+
+	policy = AbusePolicyFromConfig(config.ShareConfig{AbuseReportsPerWindow: 2, AbuseReportWindow: time.Minute, AbuseSuspendThreshold: 10})
+	assert.Equal(t, AbusePolicy{ReportsPerWindow: 2, ReportWindow: time.Minute, SuspendThreshold: 10}, policy)
+}
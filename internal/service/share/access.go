@@ -0,0 +1,219 @@
+package share
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/cache"
+	"cloudpan/internal/pkg/clock"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/ratelimit"
+	"cloudpan/internal/repository/models"
+)
+
+// 访问计数类型，用于构造计数键
+const (
+	counterKindAccess   = "access"
+	counterKindDownload = "download"
+)
+
+// counterTTL 计数在Redis中的有效期，过期后从数据库重新同步
+const counterTTL = 24 * time.Hour
+
+// AccessService 分享链接访问服务接口
+//
+// 访问者打开分享链接时解析分享码：校验分享密码后占用一次访问次数，返回文件信息和是否可下载；
+// 下载时占用一次下载次数。最大访问次数和最大下载次数通过总量计数器原子地检查和累加，
+// 多实例部署时使用Redis共享计数，并发访问不会超出上限；数据库中的次数同步累加用于展示和统计
+//
+// 使用示例：
+//
+//	service := NewAccessService(shareService, shareRepo, fileRepo, counter, nil, logger)
+//	info, err := service.Resolve(ctx, code, password, clientIP)
+//	err = service.ConsumeDownload(ctx, info.ShareID)
+type AccessService interface {
+	Resolve(ctx context.Context, code, password, clientIP string) (*ShareInfo, error)
+	ConsumeDownload(ctx context.Context, shareID uint) error
+}
+
+// AccessStore 分享访问统计数据访问，由分享仓储实现
+type AccessStore interface {
+	GetByID(ctx context.Context, id uint) (*models.FileShare, error)
+	GetByCode(ctx context.Context, code string) (*models.FileShare, error)
+	IncrementAccessCount(ctx context.Context, id uint, at time.Time) error
+	IncrementDownloadCount(ctx context.Context, id uint) error
+}
+
+// SharedFile 分享页展示的文件信息
+type SharedFile struct {
+	ID        uint      `json:"id"`         // 文件ID
+	Name      string    `json:"name"`       // 文件名
+	Size      int64     `json:"size"`       // 文件大小
+	MimeType  string    `json:"mime_type"`  // 内容类型
+	IsFolder  bool      `json:"is_folder"`  // 是否为文件夹
+	UpdatedAt time.Time `json:"updated_at"` // 更新时间
+}
+
+// ShareInfo 分享链接解析结果
+type ShareInfo struct {
+	ShareAccess
+	Downloadable      bool       `json:"downloadable"`                 // 是否允许下载
+	RemainingAccess   *int       `json:"remaining_access,omitempty"`   // 剩余访问次数，不限制时为空
+	RemainingDownload *int       `json:"remaining_download,omitempty"` // 剩余下载次数，不限制时为空
+	File              SharedFile `json:"file"`                         // 分享的文件
+	DownloadURL       string     `json:"download_url,omitempty"`       // 下载地址，由处理器根据路由填写
+}
+
+// accessService 分享链接访问服务实现
+type accessService struct {
+	shares  ShareService
+	store   AccessStore
+	files   FileReader
+	counter ratelimit.Counter
+	logger  *zap.Logger
+	clock   clock.Clock
+}
+
+// NewAccessService 创建分享链接访问服务，clk为nil时使用系统时钟
+func NewAccessService(shares ShareService, store AccessStore, files FileReader, counter ratelimit.Counter, clk clock.Clock, logger *zap.Logger) AccessService {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &accessService{
+		shares:  shares,
+		store:   store,
+		files:   files,
+		counter: counter,
+		logger:  logger,
+		clock:   clock.OrReal(clk),
+	}
+}
+
+// Resolve 解析分享码并占用一次访问次数
+//
+// 密码错误、分享失效等情况不占用访问次数
+func (s *accessService) Resolve(ctx context.Context, code, password, clientIP string) (*ShareInfo, error) {
+	access, err := s.shares.VerifyPassword(ctx, code, password, clientIP)
+	if err != nil {
+		return nil, err
+	}
+
+	share, err := s.loadShare(ctx, func() (*models.FileShare, error) { return s.store.GetByCode(ctx, code) })
+	if err != nil {
+		return nil, err
+	}
+	file, err := s.files.GetByID(ctx, share.FileID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "分享的文件不存在")
+		}
+		return nil, fmt.Errorf("获取分享文件失败: %w", err)
+	}
+	if !file.IsActive() {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "分享的文件不存在")
+	}
+
+	accessCount, ok := s.consume(ctx, share, counterKindAccess, share.AccessCount, share.MaxAccess)
+	if !ok {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "分享访问次数已达上限")
+	}
+	if err := s.store.IncrementAccessCount(ctx, share.ID, s.clock.Now()); err != nil {
+		s.logger.Warn("记录分享访问次数失败", zap.Uint("share_id", share.ID), zap.Error(err))
+	}
+
+	info := &ShareInfo{
+		ShareAccess:  *access,
+		Downloadable: canDownload(share.Permission) && !file.IsFolder,
+		File: SharedFile{
+			ID:        file.ID,
+			Name:      file.Name,
+			Size:      file.Size,
+			IsFolder:  file.IsFolder,
+			UpdatedAt: file.UpdatedAt,
+		},
+	}
+	if file.MimeType != nil {
+		info.File.MimeType = *file.MimeType
+	}
+	if share.MaxAccess != nil {
+		info.RemainingAccess = remaining(*share.MaxAccess, accessCount)
+	}
+	if share.MaxDownload != nil {
+		info.RemainingDownload = remaining(*share.MaxDownload, share.DownloadCount)
+		if *info.RemainingDownload == 0 {
+			info.Downloadable = false
+		}
+	}
+	return info, nil
+}
+
+// ConsumeDownload 占用一次下载次数，已达到最大下载次数时返回配额超出错误
+func (s *accessService) ConsumeDownload(ctx context.Context, shareID uint) error {
+	share, err := s.loadShare(ctx, func() (*models.FileShare, error) { return s.store.GetByID(ctx, shareID) })
+	if err != nil {
+		return err
+	}
+
+	if _, ok := s.consume(ctx, share, counterKindDownload, share.DownloadCount, share.MaxDownload); !ok {
+		return pkgErrors.WrapError(pkgErrors.ErrQuotaExceeded, "分享下载次数已达上限")
+	}
+	if err := s.store.IncrementDownloadCount(ctx, share.ID); err != nil {
+		s.logger.Warn("记录分享下载次数失败", zap.Uint("share_id", share.ID), zap.Error(err))
+	}
+	return nil
+}
+
+// consume 在上限内占用一次计数，返回占用后的次数
+//
+// 未设置上限时不经过计数器；计数器不可用时退化为按数据库中的次数判断
+func (s *accessService) consume(ctx context.Context, share *models.FileShare, kind string, current int, limit *int) (int, bool) {
+	if limit == nil {
+		return current + 1, true
+	}
+
+	key := cache.Keys.ShareCounter(kind, strconv.FormatUint(uint64(share.ID), 10))
+	count, ok, err := s.counter.Consume(ctx, key, current, *limit, counterTTL)
+	if err != nil {
+		s.logger.Warn("分享次数计数失败，按数据库记录判断",
+			zap.Uint("share_id", share.ID),
+			zap.String("kind", kind),
+			zap.Error(err))
+		return current + 1, current < *limit
+	}
+	if !ok {
+		s.logger.Info("分享次数已达上限",
+			zap.Uint("share_id", share.ID),
+			zap.String("kind", kind),
+			zap.Int("limit", *limit))
+	}
+	return count, ok
+}
+
+// loadShare 查询分享并将记录不存在转换为统一错误
+func (s *accessService) loadShare(ctx context.Context, load func() (*models.FileShare, error)) (*models.FileShare, error) {
+	share, err := load()
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "分享不存在")
+		}
+		return nil, fmt.Errorf("查询分享失败: %w", err)
+	}
+	return share, nil
+}
+
+// canDownload 分享权限是否允许下载
+func canDownload(permission string) bool {
+	return permission == "download" || permission == "edit"
+}
+
+// remaining 计算剩余次数，不小于0
+func remaining(limit, used int) *int {
+	left := max(limit-used, 0)
+	return &left
+}
@@ -0,0 +1,160 @@
+package share
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/ratelimit"
+	"cloudpan/internal/repository/models"
+)
+
+// memoryAccessStore 内存分享访问统计
+type memoryAccessStore struct {
+	share     *models.FileShare
+	accesses  atomic.Int32
+	downloads atomic.Int32
+}
+
+func (m *memoryAccessStore) GetByID(ctx context.Context, id uint) (*models.FileShare, error) {
+	copied := *m.share
+	return &copied, nil
+}
+
+func (m *memoryAccessStore) GetByCode(ctx context.Context, code string) (*models.FileShare, error) {
+	copied := *m.share
+	return &copied, nil
+}
+
+func (m *memoryAccessStore) IncrementAccessCount(ctx context.Context, id uint, at time.Time) error {
+	m.accesses.Add(1)
+	return nil
+}
+
+func (m *memoryAccessStore) IncrementDownloadCount(ctx context.Context, id uint) error {
+	m.downloads.Add(1)
+	return nil
+}
+
+// failingCounter 不可用的计数器
+type failingCounter struct{}
+
+func (failingCounter) Consume(ctx context.Context, key string, current, limit int, ttl time.Duration) (int, bool, error) {
+	return 0, false, errors.New("redis down")
+}
+
+func newAccessFixture(share *models.FileShare, counter ratelimit.Counter) (*accessService, *memoryAccessStore) {
+	mimeType := "application/pdf"
+	file := &models.File{Name: "报告.pdf", Size: 42, MimeType: &mimeType, Status: "active"}
+	file.ID = 1
+
+	store := &memoryAccessStore{share: share}
+	shares := &stubShareService{access: &ShareAccess{ShareID: share.ID, ShareCode: share.ShareCode, FileID: 1, Permission: share.Permission}}
+	service := NewAccessService(shares, store, memoryFiles{1: file}, counter, nil, nil).(*accessService)
+	return service, store
+}
+
+func newAccessShare(permission string) *models.FileShare {
+	share := &models.FileShare{FileID: 1, SharerID: 7, ShareCode: "abc123", Permission: permission, Status: "active"}
+	share.ID = 3
+	return share
+}
+
+func TestAccessService_Resolve(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("unlimited share", func(t *testing.T) {
+		service, store := newAccessFixture(newAccessShare("download"), ratelimit.NewMemoryCounter())
+
+		info, err := service.Resolve(ctx, "abc123", "", "1.2.3.4")
+		require.NoError(t, err)
+		assert.True(t, info.Downloadable)
+		assert.Nil(t, info.RemainingAccess)
+		assert.Nil(t, info.RemainingDownload)
+		assert.Equal(t, "报告.pdf", info.File.Name)
+		assert.Equal(t, "application/pdf", info.File.MimeType)
+		assert.Equal(t, int32(1), store.accesses.Load())
+	})
+
+	t.Run("view only share", func(t *testing.T) {
+		service, _ := newAccessFixture(newAccessShare("view"), ratelimit.NewMemoryCounter())
+
+		info, err := service.Resolve(ctx, "abc123", "", "1.2.3.4")
+		require.NoError(t, err)
+		assert.False(t, info.Downloadable)
+	})
+
+	t.Run("max access", func(t *testing.T) {
+		share := newAccessShare("download")
+		maxAccess, maxDownload := 2, 1
+		share.MaxAccess, share.AccessCount = &maxAccess, 1
+		share.MaxDownload, share.DownloadCount = &maxDownload, 1
+		service, store := newAccessFixture(share, ratelimit.NewMemoryCounter())
+
+		info, err := service.Resolve(ctx, "abc123", "", "1.2.3.4")
+		require.NoError(t, err)
+		assert.Equal(t, 0, *info.RemainingAccess)
+		assert.Equal(t, 0, *info.RemainingDownload)
+		assert.False(t, info.Downloadable, "下载次数用尽后不可下载")
+
+		_, err = service.Resolve(ctx, "abc123", "", "1.2.3.4")
+		assert.True(t, pkgErrors.IsNotFoundError(err))
+		assert.Equal(t, int32(1), store.accesses.Load())
+	})
+
+	t.Run("password rejected", func(t *testing.T) {
+		service, store := newAccessFixture(newAccessShare("download"), ratelimit.NewMemoryCounter())
+		service.shares.(*stubShareService).access = nil
+
+		_, err := service.Resolve(ctx, "abc123", "wrong", "1.2.3.4")
+		assert.Error(t, err)
+		assert.Zero(t, store.accesses.Load(), "校验失败不占用访问次数")
+	})
+}
+
+func TestAccessService_ConsumeDownload(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("concurrent downloads do not exceed limit", func(t *testing.T) {
+		share := newAccessShare("download")
+		maxDownload := 5
+		share.MaxDownload = &maxDownload
+		service, store := newAccessFixture(share, ratelimit.NewMemoryCounter())
+
+		var wg sync.WaitGroup
+		var allowed atomic.Int32
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if service.ConsumeDownload(ctx, 3) == nil {
+					allowed.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, int32(5), allowed.Load())
+		assert.Equal(t, int32(5), store.downloads.Load())
+		assert.ErrorIs(t, service.ConsumeDownload(ctx, 3), pkgErrors.ErrQuotaExceeded)
+	})
+
+	t.Run("counter unavailable falls back to database", func(t *testing.T) {
+		share := newAccessShare("download")
+		maxDownload := 1
+		share.MaxDownload = &maxDownload
+		service, _ := newAccessFixture(share, failingCounter{})
+
+		assert.NoError(t, service.ConsumeDownload(ctx, 3))
+
+		share.DownloadCount = 1
+		assert.ErrorIs(t, service.ConsumeDownload(ctx, 3), pkgErrors.ErrQuotaExceeded)
+	})
+}
@@ -0,0 +1,176 @@
+package share
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/clock"
+	basemodels "cloudpan/internal/pkg/database/models"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/idgen"
+	"cloudpan/internal/repository/models"
+)
+
+// 分享创建限制
+const (
+	MaxExpireDays        = 365 // 有效期最长天数
+	maxShareCodeAttempts = 3   // 分享码冲突时的重试次数
+)
+
+// PublicSharePath 公开分享接口路径前缀，分享链接为该前缀加分享码
+const PublicSharePath = "/api/v1/public/shares/"
+
+// CreationService 分享创建服务接口
+//
+// 文件所有者为自己的可用文件或文件夹创建分享链接，可设置权限、密码、有效期和最大访问/下载次数
+//
+// 使用示例：
+//
+//	service := NewCreationService(shareRepo, fileRepo, nil, nil, logger)
+//	share, err := service.Create(ctx, userID, &CreateShareRequest{FileID: 5, Password: "1234", ExpireDays: 7})
+type CreationService interface {
+	Create(ctx context.Context, userID uint, req *CreateShareRequest) (*models.FileShare, error)
+}
+
+// CreationStore 分享写入，由分享仓储实现
+type CreationStore interface {
+	Create(ctx context.Context, share *models.FileShare) error
+}
+
+// CreateShareRequest 创建分享请求
+type CreateShareRequest struct {
+	FileID      uint   `json:"file_id" binding:"required"` // 分享的文件ID
+	Permission  string `json:"permission"`                 // 权限类型(view/download)，默认download
+	Password    string `json:"password"`                   // 分享密码，为空表示不设置
+	ExpireDays  int    `json:"expire_days"`                // 有效天数，0表示永久有效
+	MaxAccess   *int   `json:"max_access"`                 // 最大访问次数，为空表示不限制
+	MaxDownload *int   `json:"max_download"`               // 最大下载次数，为空表示不限制
+	Template    string `json:"template"`                   // 应用的分享模板名称，为空使用默认模板
+	NoTemplate  bool   `json:"no_template"`                // 不应用默认模板
+	HideOwner   bool   `json:"hide_owner"`                 // 分享页不显示分享者
+}
+
+// creationService 分享创建服务实现
+type creationService struct {
+	store  CreationStore
+	files  FileReader
+	logger *zap.Logger
+	clock  clock.Clock
+	ids    idgen.IDGenerator
+}
+
+// NewCreationService 创建分享创建服务，clk为nil时使用系统时钟，ids为nil时使用全局配置的标识符生成器
+func NewCreationService(store CreationStore, files FileReader, clk clock.Clock, ids idgen.IDGenerator, logger *zap.Logger) CreationService {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &creationService{
+		store:  store,
+		files:  files,
+		logger: logger,
+		clock:  clock.OrReal(clk),
+		ids:    idgen.OrDefault(ids),
+	}
+}
+
+// Create 创建分享
+//
+// 分享码预先生成以便同时写入分享链接，极少数情况下与已有分享码冲突时重新生成
+func (s *creationService) Create(ctx context.Context, userID uint, req *CreateShareRequest) (*models.FileShare, error) {
+	if err := validateCreateRequest(req); err != nil {
+		return nil, err
+	}
+
+	file, err := s.files.GetByID(ctx, req.FileID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "文件不存在")
+		}
+		return nil, fmt.Errorf("获取文件失败: %w", err)
+	}
+	if file.UserID != userID {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, "只能分享自己的文件")
+	}
+	if !file.IsActive() {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "文件当前不可分享")
+	}
+
+	permission := req.Permission
+	if permission == "" {
+		permission = "download"
+	}
+	share := &models.FileShare{
+		FileID:      file.ID,
+		SharerID:    userID,
+		Permission:  permission,
+		MaxAccess:   req.MaxAccess,
+		MaxDownload: req.MaxDownload,
+		Status:      "active",
+	}
+	if req.Password != "" {
+		password := req.Password
+		share.Password = &password
+	}
+	if req.ExpireDays > 0 {
+		expiresAt := s.clock.Now().Add(time.Duration(req.ExpireDays) * 24 * time.Hour)
+		share.ExpiresAt = &expiresAt
+	}
+	if req.HideOwner {
+		share.Settings = &basemodels.JSONMap{SettingHideOwner: true}
+	}
+
+	for attempt := 1; ; attempt++ {
+		share.ShareCode = s.ids.NewShareCode()
+		share.ShareURL = PublicSharePath + share.ShareCode
+		err = s.store.Create(ctx, share)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, models.ErrIdentifierInUse) || attempt >= maxShareCodeAttempts {
+			return nil, fmt.Errorf("创建分享失败: %w", err)
+		}
+	}
+
+	s.logger.Info("分享已创建",
+		zap.Uint("share_id", share.ID),
+		zap.Uint("file_id", file.ID),
+		zap.Uint("user_id", userID),
+		zap.Bool("has_password", share.HasPassword))
+	return share, nil
+}
+
+// validateCreateRequest 校验创建分享请求
+func validateCreateRequest(req *CreateShareRequest) error {
+	if req == nil || req.FileID == 0 {
+		return pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "分享的文件不能为空")
+	}
+	return validateShareOptions(req.Permission, req.Password, req.ExpireDays, req.MaxAccess, req.MaxDownload)
+}
+
+// validateShareOptions 校验分享选项，创建分享和保存分享模板共用
+func validateShareOptions(permission, password string, expireDays int, maxAccess, maxDownload *int) error {
+	switch permission {
+	case "", "view", "download":
+	default:
+		return pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "不支持的分享权限: %s", permission)
+	}
+	if password != "" {
+		length := utf8.RuneCountInString(password)
+		if length < MinPasswordLength || length > MaxPasswordLength {
+			return pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "分享密码长度必须在%d到%d个字符之间", MinPasswordLength, MaxPasswordLength)
+		}
+	}
+	if expireDays < 0 || expireDays > MaxExpireDays {
+		return pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "有效天数必须在0到%d之间", MaxExpireDays)
+	}
+	if (maxAccess != nil && *maxAccess <= 0) || (maxDownload != nil && *maxDownload <= 0) {
+		return pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "最大访问次数和最大下载次数必须大于0")
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package share
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"cloudpan/internal/pkg/clock"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/idgen"
+	"cloudpan/internal/repository/models"
+)
+
+// memoryCreationStore 内存分享写入，前 conflicts 次返回分享码冲突
+type memoryCreationStore struct {
+	created   []*models.FileShare
+	conflicts int
+}
+
+func (m *memoryCreationStore) Create(ctx context.Context, share *models.FileShare) error {
+	if m.conflicts > 0 {
+		m.conflicts--
+		return models.ErrIdentifierInUse
+	}
+	copied := *share
+	m.created = append(m.created, &copied)
+	share.ID = uint(len(m.created))
+	return nil
+}
+
+func newCreationFixture() (*creationService, *memoryCreationStore) {
+	file := &models.File{UserID: 7, Name: "报告.pdf", Status: "active"}
+	file.ID = 1
+	trashed := &models.File{UserID: 7, Name: "old.txt", Status: "deleted"}
+	trashed.ID = 2
+
+	store := &memoryCreationStore{}
+	clk := clock.NewFake(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	service := NewCreationService(store, memoryFiles{1: file, 2: trashed}, clk, idgen.NewSequence("share"), nil).(*creationService)
+	return service, store
+}
+
+func TestCreationService_Create(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("with options", func(t *testing.T) {
+		service, store := newCreationFixture()
+		limit := 3
+		share, err := service.Create(ctx, 7, &CreateShareRequest{FileID: 1, Password: "1234", ExpireDays: 7, MaxDownload: &limit, HideOwner: true})
+		require.NoError(t, err)
+		require.Len(t, store.created, 1)
+		assert.Equal(t, "download", share.Permission)
+		assert.Equal(t, "s0000001", share.ShareCode)
+		assert.Equal(t, PublicSharePath+"s0000001", share.ShareURL)
+		assert.Equal(t, time.Date(2024, 6, 8, 12, 0, 0, 0, time.UTC), *share.ExpiresAt)
+		assert.Equal(t, 3, *share.MaxDownload)
+		require.NotNil(t, share.Settings)
+		assert.Equal(t, true, (*share.Settings)[SettingHideOwner])
+	})
+
+	t.Run("share code conflict retries", func(t *testing.T) {
+		service, store := newCreationFixture()
+		store.conflicts = 1
+		share, err := service.Create(ctx, 7, &CreateShareRequest{FileID: 1})
+		require.NoError(t, err)
+		assert.Equal(t, "s0000002", share.ShareCode, "冲突后使用新的分享码")
+		assert.Equal(t, store.created[0].ShareCode, share.ShareCode)
+	})
+
+	t.Run("other user's file", func(t *testing.T) {
+		service, store := newCreationFixture()
+		_, err := service.Create(ctx, 8, &CreateShareRequest{FileID: 1})
+		assert.True(t, pkgErrors.IsPermissionError(err))
+		assert.Empty(t, store.created)
+	})
+
+	t.Run("trashed file", func(t *testing.T) {
+		service, _ := newCreationFixture()
+		_, err := service.Create(ctx, 7, &CreateShareRequest{FileID: 2})
+		assert.ErrorIs(t, err, pkgErrors.ErrOperationNotAllowed)
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		service, _ := newCreationFixture()
+		zero := 0
+		for _, req := range []*CreateShareRequest{
+			{FileID: 1, Permission: "edit"},
+			{FileID: 1, Password: "12"},
+			{FileID: 1, ExpireDays: MaxExpireDays + 1},
+			{FileID: 1, MaxAccess: &zero},
+		} {
+			_, err := service.Create(ctx, 7, req)
+			assert.True(t, pkgErrors.IsValidationError(err), "%+v", req)
+		}
+	})
+}
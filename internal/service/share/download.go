@@ -0,0 +1,415 @@
+package share
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	basemodels "cloudpan/internal/pkg/database/models"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/imagemeta"
+	"cloudpan/internal/pkg/storage"
+	"cloudpan/internal/repository/models"
+)
+
+// 分享下载隐私设置
+const (
+	SettingStripImageLocation    = "strip_image_location" // 分享设置(FileShare.Settings)中的键
+	PreferenceCategoryShare      = "share"                // 用户偏好分类
+	PreferenceStripImageLocation = "strip_image_location" // 用户偏好键，作为该用户所有分享的默认值
+)
+
+// 隐私设置来源
+const (
+	PrivacySourceShare  = "share"  // 分享单独设置
+	PrivacySourceUser   = "user"   // 分享者的默认设置
+	PrivacySourceSystem = "system" // 系统配置
+)
+
+// sanitizedVariantPrefix 去除位置信息后的图片缓存路径前缀
+const sanitizedVariantPrefix = "variants/location-stripped"
+
+// DownloadService 分享下载服务接口
+//
+// 访问者通过公开分享链接下载文件，下载前依次检查分享密码、下载权限和流量配额。
+// 设置了最大下载次数时，每次下载占用一次下载次数。
+// 分享的是JPEG/PNG图片且启用了位置去除时，返回去除GPS和XMP元数据的副本：
+// 副本在首次下载时生成并缓存在存储中，原文件更新后自动生成新副本。
+//
+// 是否去除位置信息按 分享设置 -> 分享者默认设置 -> 系统配置 的顺序确定
+//
+// 使用示例：
+//
+//	service := NewDownloadService(shareService, accessService, shareRepo, fileRepo, userRepo, store, policy, logger)
+//	download, err := service.Open(ctx, code, password, clientIP)
+//	defer download.Content.Close()
+//	written, _ := io.Copy(w, download.Content)
+//	service.Finish(ctx, download, written)
+type DownloadService interface {
+	// 下载
+	Open(ctx context.Context, code, password, clientIP string) (*Download, error)
+	Finish(ctx context.Context, download *Download, written int64)
+
+	// 隐私设置
+	GetUserPrivacy(ctx context.Context, userID uint) (*PrivacySettings, error)
+	SetUserPrivacy(ctx context.Context, userID uint, stripImageLocation *bool) (*PrivacySettings, error)
+	SetSharePrivacy(ctx context.Context, userID, shareID uint, stripImageLocation *bool) (*PrivacySettings, error)
+}
+
+// ShareSettingsStore 分享设置数据访问，由分享仓储实现
+type ShareSettingsStore interface {
+	GetByID(ctx context.Context, id uint) (*models.FileShare, error)
+	GetByCode(ctx context.Context, code string) (*models.FileShare, error)
+	UpdateSettings(ctx context.Context, id uint, settings *basemodels.JSONMap) error
+}
+
+// DownloadQuota 下载次数限制，由分享链接访问服务实现
+type DownloadQuota interface {
+	ConsumeDownload(ctx context.Context, shareID uint) error
+}
+
+// FileReader 读取分享的文件，由文件仓储实现
+type FileReader interface {
+	GetByID(ctx context.Context, id uint) (*models.File, error)
+}
+
+// PreferenceStore 用户偏好数据访问，由用户仓储实现
+type PreferenceStore interface {
+	GetUserPreferences(ctx context.Context, userID uint, category string) ([]*models.UserPreference, error)
+	SetUserPreference(ctx context.Context, userID uint, category, key, value string) error
+	DeleteUserPreference(ctx context.Context, userID uint, category, key string) error
+}
+
+// Download 分享下载内容
+type Download struct {
+	ShareID   uint          // 分享ID
+	FileID    uint          // 文件ID
+	Name      string        // 文件名
+	MimeType  string        // 内容类型
+	Size      int64         // 内容大小(去除位置信息后可能小于原文件)
+	ModTime   time.Time     // 文件修改时间
+	Sanitized bool          // 是否已去除位置信息
+	Content   io.ReadCloser // 文件内容，调用方负责关闭
+}
+
+// PrivacySettings 分享下载隐私设置
+type PrivacySettings struct {
+	StripImageLocation bool   `json:"strip_image_location"` // 下载图片时是否去除位置信息
+	Source             string `json:"source"`               // 生效设置的来源(share/user/system)
+}
+
+// downloadService 分享下载服务实现
+type downloadService struct {
+	shares   ShareService
+	quota    DownloadQuota
+	settings ShareSettingsStore
+	files    FileReader
+	prefs    PreferenceStore
+	store    storage.Storage
+	policy   Policy
+	logger   *zap.Logger
+
+	// variantLocks 按副本路径串行生成，避免并发下载重复生成或读到未写完的副本
+	variantLocks sync.Map
+}
+
+// NewDownloadService 创建分享下载服务
+//
+// quota 可以为nil，此时不限制下载次数
+func NewDownloadService(shares ShareService, quota DownloadQuota, settings ShareSettingsStore, files FileReader, prefs PreferenceStore, store storage.Storage, policy Policy, logger *zap.Logger) DownloadService {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &downloadService{
+		shares:   shares,
+		quota:    quota,
+		settings: settings,
+		files:    files,
+		prefs:    prefs,
+		store:    store,
+		policy:   policy,
+		logger:   logger,
+	}
+}
+
+// Open 校验分享访问并打开下载内容
+//
+// 图片需要去除位置信息但结构损坏无法处理时拒绝下载，不会退回原图
+func (s *downloadService) Open(ctx context.Context, code, password, clientIP string) (*Download, error) {
+	access, err := s.shares.VerifyPassword(ctx, code, password, clientIP)
+	if err != nil {
+		return nil, err
+	}
+	if !canDownload(access.Permission) {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, "该分享不允许下载")
+	}
+	if _, err := s.shares.CheckTransfer(ctx, code); err != nil {
+		return nil, err
+	}
+
+	file, err := s.files.GetByID(ctx, access.FileID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "分享的文件不存在")
+		}
+		return nil, fmt.Errorf("获取分享文件失败: %w", err)
+	}
+	if file.IsFolder || !file.IsActive() || file.StoragePath == nil {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "分享的文件不可下载")
+	}
+
+	download := &Download{
+		ShareID:  access.ShareID,
+		FileID:   file.ID,
+		Name:     file.Name,
+		MimeType: "application/octet-stream",
+		Size:     file.Size,
+		ModTime:  file.UpdatedAt,
+	}
+	if file.MimeType != nil && *file.MimeType != "" {
+		download.MimeType = *file.MimeType
+	}
+
+	path := *file.StoragePath
+	if imagemeta.Supported(download.MimeType) {
+		share, err := s.settings.GetByCode(ctx, code)
+		if err != nil {
+			return nil, fmt.Errorf("获取分享设置失败: %w", err)
+		}
+		if s.resolvePrivacy(ctx, share).StripImageLocation {
+			if path, err = s.sanitizedVariant(ctx, file, download.MimeType); err != nil {
+				return nil, err
+			}
+			download.Sanitized = true
+		}
+	}
+
+	if download.Sanitized {
+		info, err := s.store.Stat(ctx, path)
+		if err != nil {
+			return nil, fmt.Errorf("读取图片副本失败: %w", err)
+		}
+		download.Size = info.Size
+	}
+	content, err := s.store.Open(ctx, path)
+	if err != nil {
+		if storage.IsNotFound(err) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "文件内容不存在")
+		}
+		return nil, fmt.Errorf("打开文件失败: %w", err)
+	}
+
+	// 文件确认可读后再占用下载次数，避免打开失败白白消耗次数
+	if s.quota != nil {
+		if err := s.quota.ConsumeDownload(ctx, access.ShareID); err != nil {
+			_ = content.Close()
+			return nil, err
+		}
+	}
+	download.Content = content
+	return download, nil
+}
+
+// Finish 下载结束(包括中断)后累计分享流量，失败只记录日志
+func (s *downloadService) Finish(ctx context.Context, download *Download, written int64) {
+	if download == nil {
+		return
+	}
+	if err := s.shares.RecordTransfer(ctx, download.ShareID, written); err != nil {
+		s.logger.Warn("记录分享下载流量失败",
+			zap.Uint("share_id", download.ShareID),
+			zap.Int64("bytes", written),
+			zap.Error(err))
+	}
+}
+
+// GetUserPrivacy 获取用户分享的默认隐私设置
+func (s *downloadService) GetUserPrivacy(ctx context.Context, userID uint) (*PrivacySettings, error) {
+	if userID == 0 {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "用户ID不能为空")
+	}
+
+	strip, ok, err := s.userPreference(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取用户偏好失败: %w", err)
+	}
+	if ok {
+		return &PrivacySettings{StripImageLocation: strip, Source: PrivacySourceUser}, nil
+	}
+	return &PrivacySettings{StripImageLocation: s.policy.StripImageLocation, Source: PrivacySourceSystem}, nil
+}
+
+// SetUserPrivacy 设置用户分享的默认隐私设置，nil表示恢复为系统配置
+func (s *downloadService) SetUserPrivacy(ctx context.Context, userID uint, stripImageLocation *bool) (*PrivacySettings, error) {
+	if userID == 0 {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "用户ID不能为空")
+	}
+
+	var err error
+	if stripImageLocation == nil {
+		err = s.prefs.DeleteUserPreference(ctx, userID, PreferenceCategoryShare, PreferenceStripImageLocation)
+	} else {
+		err = s.prefs.SetUserPreference(ctx, userID, PreferenceCategoryShare, PreferenceStripImageLocation, strconv.FormatBool(*stripImageLocation))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("保存用户偏好失败: %w", err)
+	}
+	return s.GetUserPrivacy(ctx, userID)
+}
+
+// SetSharePrivacy 设置单个分享的隐私设置，仅分享者可操作，nil表示沿用分享者的默认设置
+func (s *downloadService) SetSharePrivacy(ctx context.Context, userID, shareID uint, stripImageLocation *bool) (*PrivacySettings, error) {
+	share, err := s.settings.GetByID(ctx, shareID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "分享不存在")
+		}
+		return nil, fmt.Errorf("查询分享失败: %w", err)
+	}
+	if share.SharerID != userID {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, "只有分享者可以修改分享设置")
+	}
+
+	settings := basemodels.JSONMap{}
+	if share.Settings != nil {
+		for key, value := range *share.Settings {
+			settings[key] = value
+		}
+	}
+	if stripImageLocation == nil {
+		delete(settings, SettingStripImageLocation)
+	} else {
+		settings[SettingStripImageLocation] = *stripImageLocation
+	}
+	if err := s.settings.UpdateSettings(ctx, share.ID, &settings); err != nil {
+		return nil, fmt.Errorf("保存分享设置失败: %w", err)
+	}
+	share.Settings = &settings
+
+	s.logger.Info("分享隐私设置已更新",
+		zap.Uint("share_id", share.ID),
+		zap.Uint("user_id", userID),
+		zap.Bool("inherit", stripImageLocation == nil))
+	return s.resolvePrivacy(ctx, share), nil
+}
+
+// resolvePrivacy 确定分享生效的隐私设置
+//
+// 读取分享者偏好失败时按去除处理，宁可多去除也不泄露位置
+func (s *downloadService) resolvePrivacy(ctx context.Context, share *models.FileShare) *PrivacySettings {
+	if share.Settings != nil {
+		if strip, ok := (*share.Settings)[SettingStripImageLocation].(bool); ok {
+			return &PrivacySettings{StripImageLocation: strip, Source: PrivacySourceShare}
+		}
+	}
+
+	strip, ok, err := s.userPreference(ctx, share.SharerID)
+	if err != nil {
+		s.logger.Warn("读取分享者隐私偏好失败，按去除位置信息处理",
+			zap.Uint("share_id", share.ID),
+			zap.Error(err))
+		return &PrivacySettings{StripImageLocation: true, Source: PrivacySourceSystem}
+	}
+	if ok {
+		return &PrivacySettings{StripImageLocation: strip, Source: PrivacySourceUser}
+	}
+	return &PrivacySettings{StripImageLocation: s.policy.StripImageLocation, Source: PrivacySourceSystem}
+}
+
+// userPreference 读取用户的默认隐私偏好
+func (s *downloadService) userPreference(ctx context.Context, userID uint) (strip bool, ok bool, err error) {
+	preferences, err := s.prefs.GetUserPreferences(ctx, userID, PreferenceCategoryShare)
+	if err != nil {
+		return false, false, err
+	}
+	for _, preference := range preferences {
+		if preference.Key != PreferenceStripImageLocation || preference.Value == nil {
+			continue
+		}
+		if strip, err := strconv.ParseBool(*preference.Value); err == nil {
+			return strip, true, nil
+		}
+	}
+	return false, false, nil
+}
+
+// sanitizedVariant 返回去除位置信息的图片副本路径，副本不存在时生成
+//
+// 副本路径包含文件哈希(无哈希时为更新时间)，原文件变化后使用新路径；
+// 写入完成后再写就绪标记，进程中途退出留下的不完整副本会被重新生成
+func (s *downloadService) sanitizedVariant(ctx context.Context, file *models.File, mimeType string) (string, error) {
+	variantPath := path.Join(sanitizedVariantPrefix, file.UUID, contentVersion(file))
+	readyPath := variantPath + ".ready"
+
+	lock, _ := s.variantLocks.LoadOrStore(variantPath, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	ready, err := s.store.Exists(ctx, readyPath)
+	if err != nil {
+		return "", fmt.Errorf("检查图片副本失败: %w", err)
+	}
+	if ready {
+		return variantPath, nil
+	}
+
+	source, err := s.store.Open(ctx, *file.StoragePath)
+	if err != nil {
+		if storage.IsNotFound(err) {
+			return "", pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "文件内容不存在")
+		}
+		return "", fmt.Errorf("打开文件失败: %w", err)
+	}
+	defer source.Close()
+
+	writer, err := s.store.Create(ctx, variantPath)
+	if err != nil {
+		return "", fmt.Errorf("创建图片副本失败: %w", err)
+	}
+	if err := imagemeta.StripLocation(writer, source, mimeType); err != nil {
+		_ = writer.Abort()
+		if errors.Is(err, imagemeta.ErrMalformedImage) {
+			s.logger.Warn("图片结构无法解析，已阻止分享下载",
+				zap.Uint("file_id", file.ID),
+				zap.Error(err))
+			return "", pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "图片无法去除位置信息，已阻止下载")
+		}
+		return "", fmt.Errorf("去除图片位置信息失败: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		_ = writer.Abort()
+		return "", fmt.Errorf("保存图片副本失败: %w", err)
+	}
+	if err := s.store.Put(ctx, readyPath, emptyReader{}, 0); err != nil {
+		return "", fmt.Errorf("保存图片副本失败: %w", err)
+	}
+
+	s.logger.Info("已生成去除位置信息的图片副本",
+		zap.Uint("file_id", file.ID),
+		zap.String("path", variantPath))
+	return variantPath, nil
+}
+
+// emptyReader 空内容
+type emptyReader struct{}
+
+// Read 实现io.Reader接口
+func (emptyReader) Read([]byte) (int, error) {
+	return 0, io.EOF
+}
+
+// contentVersion 文件内容的版本标识，有哈希时为哈希，否则为更新时间
+func contentVersion(file *models.File) string {
+	if file.Hash != nil && *file.Hash != "" {
+		return *file.Hash
+	}
+	return strconv.FormatInt(file.UpdatedAt.UnixNano(), 10)
+}
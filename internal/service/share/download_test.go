@@ -0,0 +1,283 @@
+package share
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/jpeg"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	basemodels "cloudpan/internal/pkg/database/models"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/ratelimit"
+	"cloudpan/internal/pkg/storage"
+	"cloudpan/internal/repository/models"
+)
+
+// stubShareService 只实现下载需要的分享访问校验
+type stubShareService struct {
+	ShareService
+	access   *ShareAccess
+	recorded int64
+}
+
+func (s *stubShareService) VerifyPassword(ctx context.Context, code, password, clientIP string) (*ShareAccess, error) {
+	if s.access == nil {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "分享不存在或已失效")
+	}
+	return s.access, nil
+}
+
+func (s *stubShareService) CheckTransfer(ctx context.Context, code string) (*TransferStatus, error) {
+	return &TransferStatus{}, nil
+}
+
+func (s *stubShareService) RecordTransfer(ctx context.Context, shareID uint, bytes int64) error {
+	s.recorded += bytes
+	return nil
+}
+
+// memoryShareSettings 内存分享设置
+type memoryShareSettings struct {
+	share *models.FileShare
+}
+
+func (m *memoryShareSettings) GetByID(ctx context.Context, id uint) (*models.FileShare, error) {
+	return m.share, nil
+}
+
+func (m *memoryShareSettings) GetByCode(ctx context.Context, code string) (*models.FileShare, error) {
+	return m.share, nil
+}
+
+func (m *memoryShareSettings) UpdateSettings(ctx context.Context, id uint, settings *basemodels.JSONMap) error {
+	m.share.Settings = settings
+	return nil
+}
+
+// memoryFiles 内存文件
+type memoryFiles map[uint]*models.File
+
+func (m memoryFiles) GetByID(ctx context.Context, id uint) (*models.File, error) {
+	return m[id], nil
+}
+
+// memoryPreferences 内存用户偏好
+type memoryPreferences struct {
+	values map[string]string
+	err    error
+}
+
+func (m *memoryPreferences) GetUserPreferences(ctx context.Context, userID uint, category string) ([]*models.UserPreference, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	var preferences []*models.UserPreference
+	for key, value := range m.values {
+		value := value
+		preferences = append(preferences, &models.UserPreference{Key: key, Value: &value})
+	}
+	return preferences, nil
+}
+
+func (m *memoryPreferences) SetUserPreference(ctx context.Context, userID uint, category, key, value string) error {
+	m.values[key] = value
+	return nil
+}
+
+func (m *memoryPreferences) DeleteUserPreference(ctx context.Context, userID uint, category, key string) error {
+	delete(m.values, key)
+	return nil
+}
+
+// locatedJPEG 构造带XMP位置信息的JPEG
+func locatedJPEG(t *testing.T) []byte {
+	var encoded bytes.Buffer
+	require.NoError(t, jpeg.Encode(&encoded, image.NewGray(image.Rect(0, 0, 4, 4)), nil))
+	raw := encoded.Bytes()
+
+	payload := append([]byte("http://ns.adobe.com/xap/1.0/\x00"), `<x:xmpmeta exif:GPSLatitude="39,1.54N"/>`...)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+
+	var out bytes.Buffer
+	out.Write(raw[:2])
+	out.Write(segment)
+	out.Write(payload)
+	out.Write(raw[2:])
+	return out.Bytes()
+}
+
+type downloadFixture struct {
+	service  *downloadService
+	shares   *stubShareService
+	settings *memoryShareSettings
+	prefs    *memoryPreferences
+	store    storage.Storage
+	file     *models.File
+}
+
+func newDownloadFixture(t *testing.T, content []byte, mimeType string) *downloadFixture {
+	store, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, store.Put(context.Background(), "files/1", bytes.NewReader(content), int64(len(content))))
+
+	storagePath := "files/1"
+	hash := "hash-1"
+	file := &models.File{UUID: "file-uuid", Name: "photo.jpg", Size: int64(len(content)), MimeType: &mimeType, Hash: &hash, StoragePath: &storagePath, Status: "active"}
+	file.ID = 1
+	file.UpdatedAt = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	share := &models.FileShare{FileID: 1, SharerID: 7, ShareCode: "abc123", Permission: "download", Status: "active"}
+	share.ID = 3
+
+	fixture := &downloadFixture{
+		shares:   &stubShareService{access: &ShareAccess{ShareID: 3, ShareCode: "abc123", FileID: 1, Permission: "download"}},
+		settings: &memoryShareSettings{share: share},
+		prefs:    &memoryPreferences{values: map[string]string{}},
+		store:    store,
+		file:     file,
+	}
+	policy := DefaultPolicy()
+	policy.StripImageLocation = true
+	fixture.service = NewDownloadService(fixture.shares, nil, fixture.settings, memoryFiles{1: file}, fixture.prefs, store, policy, zap.NewNop()).(*downloadService)
+	return fixture
+}
+
+func readDownload(t *testing.T, download *Download) []byte {
+	defer download.Content.Close()
+	data, err := io.ReadAll(download.Content)
+	require.NoError(t, err)
+	return data
+}
+
+func TestDownload_StripsImageLocation(t *testing.T) {
+	ctx := context.Background()
+	original := locatedJPEG(t)
+	fixture := newDownloadFixture(t, original, "image/jpeg")
+
+	download, err := fixture.service.Open(ctx, "abc123", "", "1.2.3.4")
+	require.NoError(t, err)
+	data := readDownload(t, download)
+
+	assert.True(t, download.Sanitized)
+	assert.NotContains(t, string(data), "GPSLatitude")
+	assert.Equal(t, int64(len(data)), download.Size)
+	_, err = jpeg.Decode(bytes.NewReader(data))
+	assert.NoError(t, err)
+
+	// 副本已缓存，原文件不会再被读取
+	require.NoError(t, fixture.store.Delete(ctx, "files/1"))
+	cached, err := fixture.service.Open(ctx, "abc123", "", "1.2.3.4")
+	require.NoError(t, err)
+	assert.Equal(t, data, readDownload(t, cached))
+
+	fixture.service.Finish(ctx, cached, int64(len(data)))
+	assert.Equal(t, int64(len(data)), fixture.shares.recorded)
+}
+
+func TestDownload_PrivacyOverrides(t *testing.T) {
+	ctx := context.Background()
+	original := locatedJPEG(t)
+
+	t.Run("user preference disables stripping", func(t *testing.T) {
+		fixture := newDownloadFixture(t, original, "image/jpeg")
+		off := false
+		settings, err := fixture.service.SetUserPrivacy(ctx, 7, &off)
+		require.NoError(t, err)
+		assert.Equal(t, PrivacySourceUser, settings.Source)
+
+		download, err := fixture.service.Open(ctx, "abc123", "", "")
+		require.NoError(t, err)
+		assert.False(t, download.Sanitized)
+		assert.Equal(t, original, readDownload(t, download))
+	})
+
+	t.Run("share setting wins over user preference", func(t *testing.T) {
+		fixture := newDownloadFixture(t, original, "image/jpeg")
+		fixture.prefs.values[PreferenceStripImageLocation] = "false"
+		on := true
+		settings, err := fixture.service.SetSharePrivacy(ctx, 7, 3, &on)
+		require.NoError(t, err)
+		assert.Equal(t, &PrivacySettings{StripImageLocation: true, Source: PrivacySourceShare}, settings)
+
+		download, err := fixture.service.Open(ctx, "abc123", "", "")
+		require.NoError(t, err)
+		assert.True(t, download.Sanitized)
+		readDownload(t, download)
+
+		settings, err = fixture.service.SetSharePrivacy(ctx, 7, 3, nil)
+		require.NoError(t, err)
+		assert.Equal(t, PrivacySourceUser, settings.Source)
+		assert.False(t, settings.StripImageLocation)
+	})
+
+	t.Run("only sharer can change share", func(t *testing.T) {
+		fixture := newDownloadFixture(t, original, "image/jpeg")
+		on := true
+		_, err := fixture.service.SetSharePrivacy(ctx, 8, 3, &on)
+		assert.True(t, pkgErrors.IsPermissionError(err))
+	})
+
+	t.Run("preference read failure strips", func(t *testing.T) {
+		fixture := newDownloadFixture(t, original, "image/jpeg")
+		fixture.service.policy.StripImageLocation = false
+		fixture.prefs.err = errors.New("db down")
+
+		download, err := fixture.service.Open(ctx, "abc123", "", "")
+		require.NoError(t, err)
+		assert.True(t, download.Sanitized)
+		readDownload(t, download)
+	})
+}
+
+func TestDownload_Refusals(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("malformed image is not served", func(t *testing.T) {
+		fixture := newDownloadFixture(t, []byte("not a jpeg"), "image/jpeg")
+		_, err := fixture.service.Open(ctx, "abc123", "", "")
+		assert.True(t, pkgErrors.IsPermissionError(err))
+
+		exists, err := fixture.store.Exists(ctx, "variants/location-stripped/file-uuid/hash-1.ready")
+		require.NoError(t, err)
+		assert.False(t, exists)
+	})
+
+	t.Run("unsupported format served as-is", func(t *testing.T) {
+		fixture := newDownloadFixture(t, []byte("%PDF-1.7"), "application/pdf")
+		download, err := fixture.service.Open(ctx, "abc123", "", "")
+		require.NoError(t, err)
+		assert.False(t, download.Sanitized)
+		assert.Equal(t, "%PDF-1.7", string(readDownload(t, download)))
+	})
+
+	t.Run("view-only share", func(t *testing.T) {
+		fixture := newDownloadFixture(t, locatedJPEG(t), "image/jpeg")
+		fixture.shares.access.Permission = "view"
+		_, err := fixture.service.Open(ctx, "abc123", "", "")
+		assert.True(t, pkgErrors.IsPermissionError(err))
+	})
+
+	t.Run("download limit reached", func(t *testing.T) {
+		fixture := newDownloadFixture(t, []byte("%PDF-1.7"), "application/pdf")
+		maxDownload := 1
+		fixture.settings.share.MaxDownload = &maxDownload
+		fixture.service.quota = NewAccessService(fixture.shares, &memoryAccessStore{share: fixture.settings.share}, memoryFiles{}, ratelimit.NewMemoryCounter(), nil, nil)
+
+		download, err := fixture.service.Open(ctx, "abc123", "", "")
+		require.NoError(t, err)
+		readDownload(t, download)
+
+		_, err = fixture.service.Open(ctx, "abc123", "", "")
+		assert.ErrorIs(t, err, pkgErrors.ErrQuotaExceeded)
+	})
+}
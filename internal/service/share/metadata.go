@@ -0,0 +1,308 @@
+package share
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/cache"
+	"cloudpan/internal/pkg/clock"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+)
+
+// MetadataService 分享公开元数据服务接口
+//
+// 分享页加载时展示的文件名、大小、权限、是否需要密码等信息，每个访问者都会请求，
+// 不占用访问次数。元数据缓存在Redis中，键由分享码和版本号组成：分享更新或撤销时递增版本号，
+// 旧版本的缓存不再被读取并随TTL过期，多实例部署时无需逐个删除。
+// 元数据只用于展示，访问次数等实时状态以打开分享(Resolve)的结果为准。
+// 元数据同时包含缩略图地址、分享者显示名称和社交平台预览(Open Graph)数据，
+// 只返回可以公开的字段：设置了密码的分享不返回文件信息和缩略图，设置了隐藏分享者的分享不返回分享者
+//
+// 使用示例：
+//
+//	service := NewMetadataService(shareRepo, fileRepo, userRepo, cacheManager, nil, logger)
+//	meta, err := service.Get(ctx, code)
+//	file, err := service.ThumbnailFile(ctx, code)
+//	err = service.Invalidate(ctx, code)
+type MetadataService interface {
+	Get(ctx context.Context, code string) (*ShareMetadata, error)
+	ThumbnailFile(ctx context.Context, code string) (*models.File, error)
+	MetadataInvalidator
+}
+
+// MetadataInvalidator 分享更新或撤销后使公开元数据缓存失效
+type MetadataInvalidator interface {
+	Invalidate(ctx context.Context, code string) error
+}
+
+// MetadataCache 分享元数据缓存，由 cache.CacheManager 实现
+type MetadataCache interface {
+	Get(key string, dest interface{}) error
+	SetWithTTL(key string, value interface{}, ttl time.Duration) error
+	Increment(key string) (int64, error)
+}
+
+// MetadataStore 分享查询，由分享仓储实现
+type MetadataStore interface {
+	GetByCode(ctx context.Context, code string) (*models.FileShare, error)
+}
+
+// MetadataOwnerReader 读取分享者，由用户仓储实现
+type MetadataOwnerReader interface {
+	GetByID(ctx context.Context, id uint) (*models.User, error)
+}
+
+// SettingHideOwner 分享设置(FileShare.Settings)中的键，为true时分享页不显示分享者
+const SettingHideOwner = "hide_owner"
+
+// ShareMetadata 分享公开元数据
+type ShareMetadata struct {
+	ShareCode    string         `json:"share_code"`              // 分享码
+	Permission   string         `json:"permission"`              // 权限类型
+	HasPassword  bool           `json:"has_password"`            // 是否需要密码
+	Downloadable bool           `json:"downloadable"`            // 权限是否允许下载
+	ExpiresAt    *time.Time     `json:"expires_at,omitempty"`    // 过期时间
+	File         *SharedFile    `json:"file,omitempty"`          // 分享的文件，设置了密码的分享不公开
+	ThumbnailURL string         `json:"thumbnail_url,omitempty"` // 缩略图地址，文件没有缩略图或设置了密码时为空
+	Owner        *ShareOwner    `json:"owner,omitempty"`         // 分享者，设置了隐藏分享者时为空
+	OpenGraph    ShareOpenGraph `json:"open_graph"`              // 社交平台预览数据
+}
+
+// ShareOwner 分享页展示的分享者信息
+type ShareOwner struct {
+	DisplayName string `json:"display_name"` // 显示名称，未设置时为用户名
+}
+
+// ShareOpenGraph 分享页的Open Graph标签数据，前端渲染为og:*标签
+type ShareOpenGraph struct {
+	Title       string `json:"title"`           // og:title
+	Description string `json:"description"`     // og:description
+	Type        string `json:"type"`            // og:type
+	URL         string `json:"url"`             // og:url，分享链接路径，前端补全域名
+	Image       string `json:"image,omitempty"` // og:image，缩略图地址路径，没有缩略图时为空
+}
+
+// metadataService 分享公开元数据服务实现
+type metadataService struct {
+	store  MetadataStore
+	files  FileReader
+	owners MetadataOwnerReader
+	cache  MetadataCache
+	keys   *cache.KeyBuilder
+	ttl    time.Duration
+	logger *zap.Logger
+	clock  clock.Clock
+}
+
+// NewMetadataService 创建分享公开元数据服务
+//
+// owners 为nil时元数据不包含分享者；metadataCache 可以为nil(如Redis未初始化)，此时每次请求都查询数据库；
+// clk为nil时使用系统时钟
+func NewMetadataService(store MetadataStore, files FileReader, owners MetadataOwnerReader, metadataCache MetadataCache, clk clock.Clock, logger *zap.Logger) MetadataService {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &metadataService{
+		store:  store,
+		files:  files,
+		owners: owners,
+		cache:  metadataCache,
+		keys:   cache.NewKeyBuilder(),
+		ttl:    cache.NewTTLManager().GetTTL("file_share"),
+		logger: logger,
+		clock:  clock.OrReal(clk),
+	}
+}
+
+// Get 获取分享公开元数据，分享不存在或已失效时返回资源不存在错误
+func (s *metadataService) Get(ctx context.Context, code string) (*ShareMetadata, error) {
+	if code == "" {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "分享码不能为空")
+	}
+
+	cacheKey, cacheable := s.cacheKey(code)
+	if cacheable {
+		var cached ShareMetadata
+		if err := s.cache.Get(cacheKey, &cached); err == nil {
+			// 缓存有效期不超过分享过期时间，这里再确认一次避免时钟误差
+			if cached.ExpiresAt == nil || s.clock.Now().Before(*cached.ExpiresAt) {
+				return &cached, nil
+			}
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "分享已失效")
+		}
+	}
+
+	meta, err := s.load(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+
+	if cacheable {
+		ttl := s.ttl
+		if meta.ExpiresAt != nil {
+			ttl = min(ttl, meta.ExpiresAt.Sub(s.clock.Now()))
+		}
+		if ttl > 0 {
+			if err := s.cache.SetWithTTL(cacheKey, meta, ttl); err != nil {
+				s.logger.Warn("缓存分享元数据失败", zap.String("share_code", code), zap.Error(err))
+			}
+		}
+	}
+	return meta, nil
+}
+
+// Invalidate 递增分享元数据版本号，使已缓存的元数据失效
+func (s *metadataService) Invalidate(_ context.Context, code string) error {
+	if s.cache == nil || code == "" {
+		return nil
+	}
+	if _, err := s.cache.Increment(s.keys.ShareMetaVersion(code)); err != nil {
+		return fmt.Errorf("更新分享元数据版本失败: %w", err)
+	}
+	return nil
+}
+
+// cacheKey 返回当前版本的元数据缓存键，缓存不可用时返回false
+func (s *metadataService) cacheKey(code string) (string, bool) {
+	if s.cache == nil {
+		return "", false
+	}
+	var version int64
+	if err := s.cache.Get(s.keys.ShareMetaVersion(code), &version); err != nil && !errors.Is(err, cache.ErrCacheNotFound) {
+		// 无法确认版本号时不读写缓存，避免返回已失效的元数据
+		s.logger.Warn("读取分享元数据版本失败", zap.String("share_code", code), zap.Error(err))
+		return "", false
+	}
+	return s.keys.ShareMeta(code, version), true
+}
+
+// ThumbnailFile 返回可以公开缩略图的分享文件
+//
+// 分享有效、未设置密码且文件已生成缩略图时返回文件，否则返回资源不存在错误；
+// 不经过缓存，分享撤销或设置密码后立即生效
+func (s *metadataService) ThumbnailFile(ctx context.Context, code string) (*models.File, error) {
+	if code == "" {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "分享码不能为空")
+	}
+	share, file, err := s.loadShare(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	if share.HasPassword || file.IsFolder || file.ThumbnailURL == nil {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "分享没有缩略图")
+	}
+	return file, nil
+}
+
+// load 从数据库加载分享和文件信息生成元数据
+func (s *metadataService) load(ctx context.Context, code string) (*ShareMetadata, error) {
+	share, file, err := s.loadShare(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+
+	meta := &ShareMetadata{
+		ShareCode:    share.ShareCode,
+		Permission:   share.Permission,
+		HasPassword:  share.HasPassword,
+		Downloadable: canDownload(share.Permission) && !file.IsFolder,
+		ExpiresAt:    share.ExpiresAt,
+		Owner:        s.owner(ctx, share),
+	}
+	if !share.HasPassword {
+		meta.File = &SharedFile{
+			ID:        file.ID,
+			Name:      file.Name,
+			Size:      file.Size,
+			IsFolder:  file.IsFolder,
+			UpdatedAt: file.UpdatedAt,
+		}
+		if file.MimeType != nil {
+			meta.File.MimeType = *file.MimeType
+		}
+		if !file.IsFolder && file.ThumbnailURL != nil {
+			meta.ThumbnailURL = PublicSharePath + share.ShareCode + "/thumbnail"
+		}
+	}
+	meta.OpenGraph = openGraph(share, meta)
+	return meta, nil
+}
+
+// loadShare 查询有效的分享和分享的文件
+func (s *metadataService) loadShare(ctx context.Context, code string) (*models.FileShare, *models.File, error) {
+	share, err := s.store.GetByCode(ctx, code)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "分享不存在")
+		}
+		return nil, nil, fmt.Errorf("查询分享失败: %w", err)
+	}
+	if !share.IsAccessible() {
+		return nil, nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "分享已失效")
+	}
+
+	file, err := s.files.GetByID(ctx, share.FileID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "分享的文件不存在")
+		}
+		return nil, nil, fmt.Errorf("获取分享文件失败: %w", err)
+	}
+	if !file.IsActive() {
+		return nil, nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "分享的文件不存在")
+	}
+	return share, file, nil
+}
+
+// owner 返回分享页展示的分享者，隐藏分享者或查询失败时返回nil
+//
+// 只公开显示名称，未设置显示名称时使用用户名，不返回邮箱等其他字段
+func (s *metadataService) owner(ctx context.Context, share *models.FileShare) *ShareOwner {
+	if s.owners == nil {
+		return nil
+	}
+	if share.Settings != nil {
+		if hide, ok := (*share.Settings)[SettingHideOwner].(bool); ok && hide {
+			return nil
+		}
+	}
+
+	user, err := s.owners.GetByID(ctx, share.SharerID)
+	if err != nil {
+		s.logger.Warn("查询分享者失败", zap.Uint("share_id", share.ID), zap.Error(err))
+		return nil
+	}
+	if user.DisplayName != nil && *user.DisplayName != "" {
+		return &ShareOwner{DisplayName: *user.DisplayName}
+	}
+	return &ShareOwner{DisplayName: user.Username}
+}
+
+// openGraph 根据元数据生成Open Graph标签数据，设置了密码的分享不透露文件名
+func openGraph(share *models.FileShare, meta *ShareMetadata) ShareOpenGraph {
+	sharer := "有人"
+	if meta.Owner != nil {
+		sharer = meta.Owner.DisplayName
+	}
+
+	graph := ShareOpenGraph{Type: "website", URL: PublicSharePath + share.ShareCode, Image: meta.ThumbnailURL}
+	switch {
+	case meta.File == nil:
+		graph.Title = "加密分享"
+		graph.Description = sharer + "分享了文件，输入提取密码后查看"
+	case meta.File.IsFolder:
+		graph.Title = meta.File.Name
+		graph.Description = sharer + "分享了文件夹"
+	default:
+		graph.Title = meta.File.Name
+		graph.Description = fmt.Sprintf("%s分享了文件，大小 %s", sharer, utils.FormatBytes(meta.File.Size))
+	}
+	return graph
+}
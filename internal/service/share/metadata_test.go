@@ -0,0 +1,235 @@
+package share
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/cache"
+	"cloudpan/internal/pkg/clock"
+	basemodels "cloudpan/internal/pkg/database/models"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/repository/models"
+)
+
+// memoryMetadataCache 内存元数据缓存，按JSON序列化模拟Redis
+type memoryMetadataCache struct {
+	values map[string]string
+	ttls   map[string]time.Duration
+	err    error
+}
+
+func newMemoryMetadataCache() *memoryMetadataCache {
+	return &memoryMetadataCache{values: map[string]string{}, ttls: map[string]time.Duration{}}
+}
+
+func (m *memoryMetadataCache) Get(key string, dest interface{}) error {
+	if m.err != nil {
+		return m.err
+	}
+	value, ok := m.values[key]
+	if !ok {
+		return cache.ErrCacheNotFound
+	}
+	return json.Unmarshal([]byte(value), dest)
+}
+
+func (m *memoryMetadataCache) SetWithTTL(key string, value interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	m.values[key] = string(data)
+	m.ttls[key] = ttl
+	return nil
+}
+
+func (m *memoryMetadataCache) Increment(key string) (int64, error) {
+	n, _ := strconv.ParseInt(m.values[key], 10, 64)
+	n++
+	m.values[key] = strconv.FormatInt(n, 10)
+	return n, nil
+}
+
+// countingMetadataStore 记录查询次数的分享存储
+type countingMetadataStore struct {
+	share *models.FileShare
+	loads int
+}
+
+func (m *countingMetadataStore) GetByCode(ctx context.Context, code string) (*models.FileShare, error) {
+	m.loads++
+	copied := *m.share
+	return &copied, nil
+}
+
+func newMetadataFixture(share *models.FileShare, metadataCache MetadataCache) (*metadataService, *countingMetadataStore) {
+	mimeType := "application/pdf"
+	file := &models.File{Name: "报告.pdf", Size: 42, MimeType: &mimeType, Status: "active"}
+	file.ID = 1
+
+	store := &countingMetadataStore{share: share}
+	service := NewMetadataService(store, memoryFiles{1: file}, nil, metadataCache, clock.NewFake(testNow), nil).(*metadataService)
+	return service, store
+}
+
+func TestMetadataService_Get(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("caches by code and version", func(t *testing.T) {
+		metadataCache := newMemoryMetadataCache()
+		service, store := newMetadataFixture(newAccessShare("download"), metadataCache)
+
+		meta, err := service.Get(ctx, "abc123")
+		require.NoError(t, err)
+		assert.True(t, meta.Downloadable)
+		require.NotNil(t, meta.File)
+		assert.Equal(t, "报告.pdf", meta.File.Name)
+		assert.Contains(t, metadataCache.values, "share:meta:abc123:0")
+
+		_, err = service.Get(ctx, "abc123")
+		require.NoError(t, err)
+		assert.Equal(t, 1, store.loads)
+
+		// 递增版本号后读取新版本的键，重新查询数据库
+		require.NoError(t, service.Invalidate(ctx, "abc123"))
+		store.share.Permission = "view"
+		meta, err = service.Get(ctx, "abc123")
+		require.NoError(t, err)
+		assert.False(t, meta.Downloadable)
+		assert.Equal(t, 2, store.loads)
+		assert.Contains(t, metadataCache.values, "share:meta:abc123:1")
+	})
+
+	t.Run("ttl capped by share expiry", func(t *testing.T) {
+		// IsAccessible 按系统时间判断过期，过期时间需在未来
+		share := newAccessShare("download")
+		expiresAt := time.Now().Add(time.Hour)
+		share.ExpiresAt = &expiresAt
+		metadataCache := newMemoryMetadataCache()
+		service, _ := newMetadataFixture(share, metadataCache)
+		service.clock = clock.NewFake(expiresAt.Add(-10 * time.Minute))
+
+		_, err := service.Get(ctx, "abc123")
+		require.NoError(t, err)
+		assert.Equal(t, 10*time.Minute, metadataCache.ttls["share:meta:abc123:0"])
+	})
+
+	t.Run("password protected hides file", func(t *testing.T) {
+		service, _ := newMetadataFixture(newProtectedShare(t, "secret"), newMemoryMetadataCache())
+		service.files = memoryFiles{10: {Name: "机密.docx", Status: "active"}}
+
+		meta, err := service.Get(ctx, "abc123")
+		require.NoError(t, err)
+		assert.True(t, meta.HasPassword)
+		assert.Nil(t, meta.File)
+	})
+
+	t.Run("revoked share not found and not cached", func(t *testing.T) {
+		share := newAccessShare("download")
+		share.Status = "disabled"
+		metadataCache := newMemoryMetadataCache()
+		service, _ := newMetadataFixture(share, metadataCache)
+
+		_, err := service.Get(ctx, "abc123")
+		assert.True(t, pkgErrors.IsNotFoundError(err))
+		assert.Empty(t, metadataCache.values)
+	})
+
+	t.Run("cache unavailable falls back to database", func(t *testing.T) {
+		metadataCache := newMemoryMetadataCache()
+		metadataCache.err = errors.New("redis down")
+		service, store := newMetadataFixture(newAccessShare("download"), metadataCache)
+
+		_, err := service.Get(ctx, "abc123")
+		require.NoError(t, err)
+		_, err = service.Get(ctx, "abc123")
+		require.NoError(t, err)
+		assert.Equal(t, 2, store.loads)
+		assert.Empty(t, metadataCache.values)
+	})
+}
+
+// memoryOwners 内存分享者
+type memoryOwners map[uint]*models.User
+
+func (m memoryOwners) GetByID(_ context.Context, id uint) (*models.User, error) {
+	if user, ok := m[id]; ok {
+		return user, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func TestMetadataService_SharePage(t *testing.T) {
+	ctx := context.Background()
+	displayName := "小明"
+	owners := memoryOwners{7: {Username: "xiaoming", Email: "xm@example.com", DisplayName: &displayName}}
+	thumbnail := "/api/v1/files/1/preview?size=thumbnail"
+
+	t.Run("thumbnail owner and open graph", func(t *testing.T) {
+		service, _ := newMetadataFixture(newAccessShare("download"), nil)
+		service.owners = owners
+		service.files.(memoryFiles)[1].ThumbnailURL = &thumbnail
+
+		meta, err := service.Get(ctx, "abc123")
+		require.NoError(t, err)
+		assert.Equal(t, "/api/v1/public/shares/abc123/thumbnail", meta.ThumbnailURL)
+		assert.Equal(t, &ShareOwner{DisplayName: "小明"}, meta.Owner)
+		assert.Equal(t, ShareOpenGraph{
+			Title:       "报告.pdf",
+			Description: "小明分享了文件，大小 42 B",
+			Type:        "website",
+			URL:         "/api/v1/public/shares/abc123",
+			Image:       "/api/v1/public/shares/abc123/thumbnail",
+		}, meta.OpenGraph)
+
+		// 只公开显示名称
+		data, err := json.Marshal(meta)
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), "xm@example.com")
+		assert.NotContains(t, string(data), "xiaoming")
+
+		file, err := service.ThumbnailFile(ctx, "abc123")
+		require.NoError(t, err)
+		assert.Equal(t, uint(1), file.ID)
+	})
+
+	t.Run("hidden owner and missing thumbnail", func(t *testing.T) {
+		share := newAccessShare("download")
+		share.Settings = &basemodels.JSONMap{SettingHideOwner: true}
+		service, _ := newMetadataFixture(share, nil)
+		service.owners = owners
+
+		meta, err := service.Get(ctx, "abc123")
+		require.NoError(t, err)
+		assert.Nil(t, meta.Owner)
+		assert.Empty(t, meta.ThumbnailURL)
+		assert.Equal(t, "有人分享了文件，大小 42 B", meta.OpenGraph.Description)
+
+		_, err = service.ThumbnailFile(ctx, "abc123")
+		assert.True(t, pkgErrors.IsNotFoundError(err))
+	})
+
+	t.Run("password protected reveals only owner", func(t *testing.T) {
+		service, _ := newMetadataFixture(newProtectedShare(t, "secret"), nil)
+		service.owners = memoryOwners{7: {Username: "xiaoming"}}
+		service.files = memoryFiles{10: {Name: "机密.docx", Status: "active", ThumbnailURL: &thumbnail}}
+
+		meta, err := service.Get(ctx, "abc123")
+		require.NoError(t, err)
+		assert.Empty(t, meta.ThumbnailURL)
+		assert.Equal(t, "xiaoming", meta.Owner.DisplayName)
+		assert.Equal(t, "加密分享", meta.OpenGraph.Title)
+		assert.NotContains(t, meta.OpenGraph.Description, "机密")
+
+		_, err = service.ThumbnailFile(ctx, "abc123")
+		assert.True(t, pkgErrors.IsNotFoundError(err))
+	})
+}
@@ -0,0 +1,420 @@
+package share
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/clock"
+	"cloudpan/internal/pkg/config"
+	dbmodels "cloudpan/internal/pkg/database/models"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/moderation"
+	"cloudpan/internal/pkg/storage"
+	"cloudpan/internal/repository/models"
+)
+
+// 复核操作
+const (
+	ModerationDecisionApprove = "approve" // 放行：允许公开分享
+	ModerationDecisionReject  = "reject"  // 禁止：停用文件的有效分享，不能再公开分享
+)
+
+// MaxModerationNoteLength 复核备注的最大长度(字符数)
+const MaxModerationNoteLength = 500
+
+// DefaultModerationMaxFileSize 默认审核的图片大小上限(20MB)
+const DefaultModerationMaxFileSize int64 = 20 << 20
+
+// ContentModerator 公开分享前的内容审核，由分享创建处理器在创建分享前调用
+type ContentModerator interface {
+	// Check 审核要公开分享的文件，禁止公开分享时返回 ErrOperationNotAllowed
+	Check(ctx context.Context, fileID uint) error
+}
+
+// ModerationService 分享图片内容审核服务接口
+//
+// 图片文件创建公开分享前调用审核接口，最高置信度达到禁止阈值时拒绝创建分享，达到复核阈值时
+// 允许分享但进入管理员复核队列，两种情况都提醒全部管理员。每个文件保留一条审核结果，
+// 文件内容未变化时直接使用已有结果，审核接口不可用时允许分享并在下次分享时重新审核。
+// 文件夹、非图片、超过大小上限或内容不可读取的文件不审核。
+// 管理员复核后以复核结论为准：放行后可以正常分享，禁止时停用文件的有效分享并通知所有者
+//
+// 使用示例：
+//
+//	service := NewModerationService(resultRepo, fileRepo, shareRepo, userRepo, notificationRepo, metadataService, store, scanner, ModerationPolicyFromConfig(cfg), nil, logger)
+//	err := service.Check(ctx, fileID)
+//	results, total, err := service.ListResults(ctx, []string{models.ModerationStatusFlagged}, true, 1, 20)
+//	review, err := service.Override(ctx, adminID, resultID, ModerationDecisionReject, "色情图片")
+type ModerationService interface {
+	ContentModerator
+	ListResults(ctx context.Context, statuses []string, unreviewedOnly bool, page, pageSize int) ([]*models.ContentModerationResult, int64, error)
+	Override(ctx context.Context, adminID, resultID uint, decision, note string) (*ModerationReview, error)
+}
+
+// ModerationStore 内容审核结果数据访问，由内容审核仓储实现
+type ModerationStore interface {
+	Save(ctx context.Context, result *models.ContentModerationResult) error
+	GetByID(ctx context.Context, id uint) (*models.ContentModerationResult, error)
+	GetByFileID(ctx context.Context, fileID uint) (*models.ContentModerationResult, error)
+	List(ctx context.Context, statuses []string, unreviewedOnly bool, limit, offset int) ([]*models.ContentModerationResult, int64, error)
+	Review(ctx context.Context, id uint, override string, reviewedBy uint, note *string, reviewedAt time.Time) error
+}
+
+// ModerationShareStore 复核禁止时停用分享使用的数据访问，由分享仓储实现
+type ModerationShareStore interface {
+	ListActiveByFile(ctx context.Context, fileID uint) ([]*models.FileShare, error)
+	TransitionStatus(ctx context.Context, id uint, from []string, to string) (bool, error)
+}
+
+// ModerationReview 复核结果
+type ModerationReview struct {
+	FileID         uint   `json:"file_id"`         // 文件ID
+	Override       string `json:"override"`        // 复核结论(approved/rejected)
+	DisabledShares int    `json:"disabled_shares"` // 禁止时停用的分享数
+}
+
+// ModerationPolicy 内容审核策略
+type ModerationPolicy struct {
+	MaxFileSize    int64   // 审核的图片大小上限(字节)
+	FlagThreshold  float64 // 最高置信度达到该值时进入复核队列
+	BlockThreshold float64 // 最高置信度达到该值时禁止公开分享，大于1时只复核不禁止
+}
+
+// DefaultModerationPolicy 默认内容审核策略
+func DefaultModerationPolicy() ModerationPolicy {
+	return ModerationPolicy{
+		MaxFileSize:    DefaultModerationMaxFileSize,
+		FlagThreshold:  0.6,
+		BlockThreshold: 0.9,
+	}
+}
+
+// ModerationPolicyFromConfig 从内容审核配置生成策略，未配置的项使用默认值
+func ModerationPolicyFromConfig(cfg config.ModerationConfig) ModerationPolicy {
+	policy := DefaultModerationPolicy()
+	if cfg.MaxFileSize > 0 {
+		policy.MaxFileSize = cfg.MaxFileSize
+	}
+	if cfg.FlagThreshold > 0 {
+		policy.FlagThreshold = cfg.FlagThreshold
+	}
+	if cfg.BlockThreshold > 0 {
+		policy.BlockThreshold = cfg.BlockThreshold
+	}
+	return policy
+}
+
+// verdict 根据最高置信度得出审核结论
+func (p ModerationPolicy) verdict(score float64) string {
+	switch {
+	case score >= p.BlockThreshold:
+		return models.ModerationStatusBlocked
+	case score >= p.FlagThreshold:
+		return models.ModerationStatusFlagged
+	default:
+		return models.ModerationStatusClean
+	}
+}
+
+// moderationService 分享图片内容审核服务实现
+type moderationService struct {
+	results  ModerationStore
+	files    FileReader
+	shares   ModerationShareStore
+	admins   AdminLister
+	notifier NotificationWriter
+	metadata MetadataInvalidator
+	store    storage.Storage
+	scanner  moderation.Scanner
+	policy   ModerationPolicy
+	logger   *zap.Logger
+	clock    clock.Clock
+}
+
+// NewModerationService 创建分享图片内容审核服务
+//
+// scanner 为nil时不审核，仍可复核已有结果；admins 或 notifier 为nil时不提醒管理员，notifier 为nil时也不通知文件所有者；
+// metadata 可以为nil，此时停用分享后不清除公开元数据缓存；clk为nil时使用系统时钟
+func NewModerationService(results ModerationStore, files FileReader, shares ModerationShareStore, admins AdminLister, notifier NotificationWriter, metadata MetadataInvalidator, store storage.Storage, scanner moderation.Scanner, policy ModerationPolicy, clk clock.Clock, logger *zap.Logger) ModerationService {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &moderationService{
+		results:  results,
+		files:    files,
+		shares:   shares,
+		admins:   admins,
+		notifier: notifier,
+		metadata: metadata,
+		store:    store,
+		scanner:  scanner,
+		policy:   policy,
+		logger:   logger,
+		clock:    clock.OrReal(clk),
+	}
+}
+
+// Check 审核要公开分享的文件
+//
+// 文件不存在时不处理，由创建分享返回错误；审核接口不可用时记录为failed并允许分享
+func (s *moderationService) Check(ctx context.Context, fileID uint) error {
+	if s.scanner == nil {
+		return nil
+	}
+
+	file, err := s.files.GetByID(ctx, fileID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return fmt.Errorf("获取文件失败: %w", err)
+	}
+	if file.IsFolder || !file.IsImage() {
+		return nil
+	}
+
+	result, err := s.results.GetByFileID(ctx, file.ID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return pkgErrors.WrapError(err, "查询内容审核结果失败")
+	}
+	version := contentVersion(file)
+	if result != nil && result.FileHash == version && result.Status != models.ModerationStatusFailed {
+		return blockedError(result)
+	}
+
+	now := s.clock.Now()
+	if file.StoragePath == nil || file.StorageType != s.store.Type() || file.Size > s.policy.MaxFileSize || !file.IsReadable(now) {
+		return nil
+	}
+
+	if result == nil {
+		result = &models.ContentModerationResult{FileID: file.ID}
+	}
+	result.UserID = file.UserID
+	result.FileName = file.Name
+	result.FileHash = version
+	result.ScannedAt = now
+	result.Override, result.ReviewedBy, result.ReviewedAt, result.ReviewNote = nil, nil, nil, nil
+	if err := s.scan(ctx, file, result); err != nil {
+		return err
+	}
+	if err := s.results.Save(ctx, result); err != nil {
+		return pkgErrors.WrapError(err, "保存内容审核结果失败")
+	}
+
+	if result.Status == models.ModerationStatusFlagged || result.Status == models.ModerationStatusBlocked {
+		s.logger.Warn("分享图片内容审核未通过",
+			zap.Uint("file_id", file.ID),
+			zap.Uint("user_id", file.UserID),
+			zap.String("status", result.Status),
+			zap.String("label", result.Label),
+			zap.Float64("score", result.Score))
+		s.notifyAdmins(ctx, result)
+	}
+	return blockedError(result)
+}
+
+// scan 调用审核接口并将结论写入result，审核接口不可用时结论为failed
+func (s *moderationService) scan(ctx context.Context, file *models.File, result *models.ContentModerationResult) error {
+	content, err := s.store.Open(ctx, *file.StoragePath)
+	if err != nil {
+		if storage.IsNotFound(err) {
+			return pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "文件内容不存在")
+		}
+		return fmt.Errorf("打开文件失败: %w", err)
+	}
+	defer content.Close()
+
+	scanned, err := s.scanner.Scan(ctx, content, *file.MimeType)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		s.logger.Error("分享图片内容审核失败，允许分享",
+			zap.Uint("file_id", file.ID),
+			zap.Error(err))
+		result.Status, result.Label, result.Score, result.Labels = models.ModerationStatusFailed, "", 0, nil
+		return nil
+	}
+
+	top := scanned.Top()
+	labels := make(dbmodels.JSONMap, len(scanned.Labels))
+	for _, label := range scanned.Labels {
+		labels[label.Name] = label.Score
+	}
+	result.Status = s.policy.verdict(top.Score)
+	result.Label, result.Score, result.Labels = top.Name, top.Score, &labels
+	return nil
+}
+
+// ListResults 分页查询审核结果，statuses为空时查询全部结论，unreviewedOnly为true时只查询未复核的结果
+func (s *moderationService) ListResults(ctx context.Context, statuses []string, unreviewedOnly bool, page, pageSize int) ([]*models.ContentModerationResult, int64, error) {
+	for _, status := range statuses {
+		switch status {
+		case models.ModerationStatusClean, models.ModerationStatusFlagged, models.ModerationStatusBlocked, models.ModerationStatusFailed:
+		default:
+			return nil, 0, pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "审核结论无效: %s", status)
+		}
+	}
+	if page < 1 || pageSize < 1 {
+		return nil, 0, pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "分页参数无效")
+	}
+
+	results, total, err := s.results.List(ctx, statuses, unreviewedOnly, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, 0, pkgErrors.WrapError(err, "查询内容审核结果失败")
+	}
+	return results, total, nil
+}
+
+// Override 复核审核结果，可以修改已有的复核结论
+//
+// 禁止时先停用文件的有效分享再记录复核结论，记录失败时重试不会重复停用；放行不恢复已停用的分享
+func (s *moderationService) Override(ctx context.Context, adminID, resultID uint, decision, note string) (*ModerationReview, error) {
+	var override string
+	switch decision {
+	case ModerationDecisionApprove:
+		override = models.ModerationOverrideApproved
+	case ModerationDecisionReject:
+		override = models.ModerationOverrideRejected
+	default:
+		return nil, pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "复核操作必须是 %s 或 %s", ModerationDecisionApprove, ModerationDecisionReject)
+	}
+	note = strings.TrimSpace(note)
+	if utf8.RuneCountInString(note) > MaxModerationNoteLength {
+		return nil, pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "复核备注不能超过%d个字符", MaxModerationNoteLength)
+	}
+
+	result, err := s.results.GetByID(ctx, resultID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "审核结果不存在")
+		}
+		return nil, pkgErrors.WrapError(err, "查询内容审核结果失败")
+	}
+
+	review := &ModerationReview{FileID: result.FileID, Override: override}
+	if override == models.ModerationOverrideRejected {
+		if review.DisabledShares, err = s.disableShares(ctx, result); err != nil {
+			return nil, err
+		}
+	}
+
+	var notePtr *string
+	if note != "" {
+		notePtr = &note
+	}
+	if err := s.results.Review(ctx, result.ID, override, adminID, notePtr, s.clock.Now()); err != nil {
+		return nil, pkgErrors.WrapError(err, "保存复核结论失败")
+	}
+
+	s.logger.Info("分享图片内容审核已复核",
+		zap.Uint("file_id", result.FileID),
+		zap.Uint("admin_id", adminID),
+		zap.String("override", override),
+		zap.Int("disabled_shares", review.DisabledShares))
+	return review, nil
+}
+
+// disableShares 停用文件的有效分享并通知文件所有者，返回停用的分享数
+func (s *moderationService) disableShares(ctx context.Context, result *models.ContentModerationResult) (int, error) {
+	shares, err := s.shares.ListActiveByFile(ctx, result.FileID)
+	if err != nil {
+		return 0, pkgErrors.WrapError(err, "查询文件的分享失败")
+	}
+
+	disabled := 0
+	for _, share := range shares {
+		changed, err := s.shares.TransitionStatus(ctx, share.ID, []string{models.ShareStatusActive, models.ShareStatusSuspended}, models.ShareStatusDisabled)
+		if err != nil {
+			return disabled, pkgErrors.WrapError(err, "停用分享失败")
+		}
+		if !changed {
+			continue
+		}
+		disabled++
+		if s.metadata != nil {
+			if err := s.metadata.Invalidate(ctx, share.ShareCode); err != nil {
+				s.logger.Warn("清除分享元数据缓存失败", zap.Uint("share_id", share.ID), zap.Error(err))
+			}
+		}
+	}
+	if disabled > 0 {
+		s.notifyOwner(ctx, result, disabled)
+	}
+	return disabled, nil
+}
+
+// notifyAdmins 提醒全部管理员复核，失败只记录日志
+func (s *moderationService) notifyAdmins(ctx context.Context, result *models.ContentModerationResult) {
+	if s.admins == nil || s.notifier == nil {
+		return
+	}
+
+	adminIDs, err := s.admins.ListActiveUserIDsByRole(ctx, models.RoleNameAdmin)
+	if err != nil {
+		s.logger.Error("查询管理员失败，无法发送内容审核提醒", zap.Uint("file_id", result.FileID), zap.Error(err))
+		return
+	}
+	title, content := "分享图片疑似违规", fmt.Sprintf("图片 %s 在分享时被标记为疑似违规(%s，置信度%.2f)，请在内容审核队列中复核。", result.FileName, result.Label, result.Score)
+	if result.Status == models.ModerationStatusBlocked {
+		title, content = "分享图片已被禁止", fmt.Sprintf("图片 %s 在分享时被判定为违规(%s，置信度%.2f)，已禁止公开分享，如为误判请在内容审核队列中放行。", result.FileName, result.Label, result.Score)
+	}
+	for _, adminID := range adminIDs {
+		fileID := result.FileID
+		notification := &models.Notification{
+			UserID:      adminID,
+			Type:        models.NotificationTypeContentModeration,
+			Title:       title,
+			Content:     content,
+			Priority:    models.NotificationPriorityHigh,
+			RelatedType: "file",
+			RelatedID:   &fileID,
+			Data: &dbmodels.JSONMap{
+				"status": result.Status,
+				"label":  result.Label,
+				"score":  result.Score,
+			},
+		}
+		if err := s.notifier.Create(ctx, notification); err != nil {
+			s.logger.Error("发送内容审核提醒失败", zap.Uint("file_id", result.FileID), zap.Uint("admin_id", adminID), zap.Error(err))
+		}
+	}
+}
+
+// notifyOwner 通知文件所有者分享因复核禁止被停用，失败只记录日志
+func (s *moderationService) notifyOwner(ctx context.Context, result *models.ContentModerationResult, disabled int) {
+	if s.notifier == nil {
+		return
+	}
+
+	fileID := result.FileID
+	notification := &models.Notification{
+		UserID:      result.UserID,
+		Type:        models.NotificationTypeContentModeration,
+		Title:       "分享链接已停用",
+		Content:     fmt.Sprintf("您的图片 %s 经管理员复核确认违规，%d 个分享链接已被停用，且不能再公开分享。", result.FileName, disabled),
+		Priority:    models.NotificationPriorityHigh,
+		RelatedType: "file",
+		RelatedID:   &fileID,
+		Data:        &dbmodels.JSONMap{"disabled_shares": disabled},
+	}
+	if err := s.notifier.Create(ctx, notification); err != nil {
+		s.logger.Error("发送内容审核处理通知失败", zap.Uint("file_id", result.FileID), zap.Error(err))
+	}
+}
+
+// blockedError 审核结果禁止公开分享时返回错误
+func blockedError(result *models.ContentModerationResult) error {
+	if result.Blocked() {
+		return pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "图片内容违反分享规范，不能公开分享")
+	}
+	return nil
+}
@@ -0,0 +1,290 @@
+package share
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/clock"
+	"cloudpan/internal/pkg/config"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/moderation"
+	"cloudpan/internal/pkg/storage"
+	"cloudpan/internal/repository/models"
+)
+
+// memoryModerationResults 内存内容审核结果
+type memoryModerationResults struct {
+	results []*models.ContentModerationResult
+}
+
+func (m *memoryModerationResults) Save(ctx context.Context, result *models.ContentModerationResult) error {
+	for i, existing := range m.results {
+		if existing.FileID == result.FileID {
+			result.ID = existing.ID
+			copied := *result
+			m.results[i] = &copied
+			return nil
+		}
+	}
+	result.ID = uint(len(m.results) + 1)
+	copied := *result
+	m.results = append(m.results, &copied)
+	return nil
+}
+
+func (m *memoryModerationResults) GetByID(ctx context.Context, id uint) (*models.ContentModerationResult, error) {
+	for _, result := range m.results {
+		if result.ID == id {
+			copied := *result
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *memoryModerationResults) GetByFileID(ctx context.Context, fileID uint) (*models.ContentModerationResult, error) {
+	for _, result := range m.results {
+		if result.FileID == fileID {
+			copied := *result
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *memoryModerationResults) List(ctx context.Context, statuses []string, unreviewedOnly bool, limit, offset int) ([]*models.ContentModerationResult, int64, error) {
+	var matched []*models.ContentModerationResult
+	for _, result := range m.results {
+		if (len(statuses) == 0 || contains(statuses, result.Status)) && (!unreviewedOnly || result.Override == nil) {
+			matched = append(matched, result)
+		}
+	}
+	total := int64(len(matched))
+	if offset >= len(matched) {
+		return nil, total, nil
+	}
+	return matched[offset:min(offset+limit, len(matched))], total, nil
+}
+
+func (m *memoryModerationResults) Review(ctx context.Context, id uint, override string, reviewedBy uint, note *string, reviewedAt time.Time) error {
+	for _, result := range m.results {
+		if result.ID == id {
+			result.Override, result.ReviewedBy, result.ReviewedAt, result.ReviewNote = &override, &reviewedBy, &reviewedAt, note
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+func contains(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+// memoryModerationShares 内存分享，按文件查询有效分享
+type memoryModerationShares struct {
+	shares []*models.FileShare
+}
+
+func (m *memoryModerationShares) ListActiveByFile(ctx context.Context, fileID uint) ([]*models.FileShare, error) {
+	var active []*models.FileShare
+	for _, share := range m.shares {
+		if share.FileID == fileID && (share.Status == models.ShareStatusActive || share.Status == models.ShareStatusSuspended) {
+			copied := *share
+			active = append(active, &copied)
+		}
+	}
+	return active, nil
+}
+
+func (m *memoryModerationShares) TransitionStatus(ctx context.Context, id uint, from []string, to string) (bool, error) {
+	for _, share := range m.shares {
+		if share.ID == id && contains(from, share.Status) {
+			share.Status = to
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// stubScanner 返回固定置信度的审核接口，记录调用次数
+type stubScanner struct {
+	score float64
+	err   error
+	calls int
+}
+
+func (s *stubScanner) Scan(ctx context.Context, src io.Reader, mimeType string) (*moderation.Result, error) {
+	s.calls++
+	if s.err != nil {
+		return nil, s.err
+	}
+	if _, err := io.ReadAll(src); err != nil {
+		return nil, err
+	}
+	return &moderation.Result{Labels: []moderation.Label{{Name: "violence", Score: 0.1}, {Name: "nsfw", Score: s.score}}}, nil
+}
+
+type moderationFixture struct {
+	service     *moderationService
+	results     *memoryModerationResults
+	shares      *memoryModerationShares
+	scanner     *stubScanner
+	notifier    *recordingNotifier
+	invalidator *recordingInvalidator
+	file        *models.File
+}
+
+func newModerationFixture(t *testing.T, score float64) *moderationFixture {
+	store, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	content := []byte("image bytes")
+	require.NoError(t, store.Put(context.Background(), "files/1", bytes.NewReader(content), int64(len(content))))
+
+	mimeType, storagePath, hash := "image/png", "files/1", "hash-1"
+	file := &models.File{UserID: 7, Name: "photo.png", Size: int64(len(content)), MimeType: &mimeType, Hash: &hash,
+		StoragePath: &storagePath, StorageType: store.Type(), Status: "active"}
+	file.ID = 1
+
+	shares := []*models.FileShare{
+		{FileID: 1, SharerID: 7, ShareCode: "abc123", Status: models.ShareStatusActive},
+		{FileID: 1, SharerID: 7, ShareCode: "old456", Status: models.ShareStatusExpired},
+	}
+	shares[0].ID, shares[1].ID = 3, 4
+
+	f := &moderationFixture{
+		results:     &memoryModerationResults{},
+		shares:      &memoryModerationShares{shares: shares},
+		scanner:     &stubScanner{score: score},
+		notifier:    &recordingNotifier{},
+		invalidator: &recordingInvalidator{},
+		file:        file,
+	}
+	f.service = NewModerationService(f.results, memoryFiles{1: file}, f.shares, staticAdmins{1, 2}, f.notifier, f.invalidator,
+		store, f.scanner, DefaultModerationPolicy(), clock.NewFake(testNow), nil).(*moderationService)
+	return f
+}
+
+func TestModerationService_Check(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("clean image is scanned once per content", func(t *testing.T) {
+		f := newModerationFixture(t, 0.2)
+		require.NoError(t, f.service.Check(ctx, 1))
+		require.NoError(t, f.service.Check(ctx, 1))
+		assert.Equal(t, 1, f.scanner.calls, "内容未变化时使用已有结果")
+		require.Len(t, f.results.results, 1)
+		result := f.results.results[0]
+		assert.Equal(t, models.ModerationStatusClean, result.Status)
+		assert.Equal(t, "nsfw", result.Label)
+		assert.Empty(t, f.notifier.notifications)
+
+		hash := "hash-2"
+		f.file.Hash = &hash
+		require.NoError(t, f.service.Check(ctx, 1))
+		assert.Equal(t, 2, f.scanner.calls, "内容变化后重新审核")
+	})
+
+	t.Run("flagged image is shared and queued for review", func(t *testing.T) {
+		f := newModerationFixture(t, 0.7)
+		require.NoError(t, f.service.Check(ctx, 1))
+		assert.Equal(t, models.ModerationStatusFlagged, f.results.results[0].Status)
+		assert.Equal(t, []uint{1, 2}, f.notifier.recipients())
+	})
+
+	t.Run("blocked image cannot be shared until approved", func(t *testing.T) {
+		f := newModerationFixture(t, 0.95)
+		err := f.service.Check(ctx, 1)
+		assert.True(t, errors.Is(err, pkgErrors.ErrOperationNotAllowed))
+		assert.Equal(t, models.ModerationStatusBlocked, f.results.results[0].Status)
+		assert.Equal(t, []uint{1, 2}, f.notifier.recipients())
+
+		_, err = f.service.Override(ctx, 1, f.results.results[0].ID, ModerationDecisionApprove, "误判")
+		require.NoError(t, err)
+		assert.NoError(t, f.service.Check(ctx, 1))
+		assert.Equal(t, 1, f.scanner.calls)
+	})
+
+	t.Run("scanner failure allows sharing and retries later", func(t *testing.T) {
+		f := newModerationFixture(t, 0.95)
+		f.scanner.err = errors.New("model unavailable")
+		require.NoError(t, f.service.Check(ctx, 1))
+		assert.Equal(t, models.ModerationStatusFailed, f.results.results[0].Status)
+
+		f.scanner.err = nil
+		assert.Error(t, f.service.Check(ctx, 1), "之前审核失败时重新审核")
+		assert.Equal(t, 2, f.scanner.calls)
+	})
+
+	t.Run("skipped files", func(t *testing.T) {
+		f := newModerationFixture(t, 0.95)
+		f.file.MimeType = nil
+		assert.NoError(t, f.service.Check(ctx, 1), "非图片不审核")
+
+		mimeType := "image/png"
+		f.file.MimeType = &mimeType
+		f.file.Size = DefaultModerationMaxFileSize + 1
+		assert.NoError(t, f.service.Check(ctx, 1), "超过大小上限不审核")
+		assert.Zero(t, f.scanner.calls)
+
+		f.service.scanner = nil
+		f.file.Size = 1
+		assert.NoError(t, f.service.Check(ctx, 1), "未配置审核接口")
+	})
+}
+
+func TestModerationService_Override(t *testing.T) {
+	ctx := context.Background()
+	f := newModerationFixture(t, 0.7)
+	require.NoError(t, f.service.Check(ctx, 1))
+	f.notifier.notifications = nil
+
+	_, err := f.service.Override(ctx, 1, f.results.results[0].ID, "delete", "")
+	assert.True(t, pkgErrors.IsValidationError(err))
+	_, err = f.service.Override(ctx, 1, 99, ModerationDecisionReject, "")
+	assert.True(t, pkgErrors.IsNotFoundError(err))
+
+	review, err := f.service.Override(ctx, 9, f.results.results[0].ID, ModerationDecisionReject, "  违规图片  ")
+	require.NoError(t, err)
+	assert.Equal(t, &ModerationReview{FileID: 1, Override: models.ModerationOverrideRejected, DisabledShares: 1}, review)
+	assert.Equal(t, models.ShareStatusDisabled, f.shares.shares[0].Status)
+	assert.Equal(t, models.ShareStatusExpired, f.shares.shares[1].Status, "已失效的分享保持原状态")
+	assert.Equal(t, []string{"abc123"}, f.invalidator.codes)
+	assert.Equal(t, []uint{7}, f.notifier.recipients())
+
+	result := f.results.results[0]
+	assert.Equal(t, uint(9), *result.ReviewedBy)
+	assert.Equal(t, "违规图片", *result.ReviewNote)
+	assert.True(t, errors.Is(f.service.Check(ctx, 1), pkgErrors.ErrOperationNotAllowed), "复核禁止后不能再分享")
+
+	results, total, err := f.service.ListResults(ctx, []string{models.ModerationStatusFlagged}, true, 1, 20)
+	require.NoError(t, err)
+	assert.Zero(t, total, "已复核的结果不在待复核队列中")
+	assert.Empty(t, results)
+	_, total, err = f.service.ListResults(ctx, nil, false, 1, 20)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	_, _, err = f.service.ListResults(ctx, []string{"weird"}, false, 1, 20)
+	assert.True(t, pkgErrors.IsValidationError(err))
+}
+
+func TestModerationPolicyFromConfig(t *testing.T) {
+	assert.Equal(t, DefaultModerationPolicy(), ModerationPolicyFromConfig(config.ModerationConfig{}))
+
+	policy := ModerationPolicyFromConfig(config.ModerationConfig{MaxFileSize: 1 << 20, FlagThreshold: 0.5, BlockThreshold: 1.1})
+	assert.Equal(t, int64(1<<20), policy.MaxFileSize)
+	assert.Equal(t, models.ModerationStatusFlagged, policy.verdict(0.99), "禁止阈值大于1时只复核不禁止")
+	assert.Equal(t, models.ModerationStatusClean, policy.verdict(0.4))
+}
@@ -0,0 +1,137 @@
+package share
+
+import (
+	"context"
+	"time"
+
+	"cloudpan/internal/pkg/config"
+	"cloudpan/internal/repository/models"
+)
+
+// ShareService 分享访问保护服务接口
+//
+// 1. 密码保护：分享密码以BCrypt哈希存储，访问者验证密码时按 分享+IP 限流，
+// 同一分享累计错误次数达到阈值后临时锁定并通知分享者
+// 2. 流量配额：按自然月统计每个分享的下载流量，超过分享者套餐的上限后拒绝新的下载
+// 3. 撤销分享：分享者停用分享链接，之后的访问返回分享已失效
+//
+// 修改密码和撤销分享后使分享的公开元数据缓存失效
+//
+// 使用示例：
+//
+//	service := NewShareService(shareRepo, settingRepo, userRepo, notificationRepo, metadataService, limiter, PolicyFromConfig(cfg), nil, logger)
+//	access, err := service.VerifyPassword(ctx, code, password, c.ClientIP())
+//	err = service.SetPassword(ctx, userID, shareID, "new-password")
+//	err = service.Revoke(ctx, userID, shareID)
+//	status, err := service.CheckTransfer(ctx, code)
+//	err = service.RecordTransfer(ctx, status.ShareID, written)
+type ShareService interface {
+	// 密码保护
+	SetPassword(ctx context.Context, userID, shareID uint, password string) error
+	VerifyPassword(ctx context.Context, code, password, clientIP string) (*ShareAccess, error)
+
+	// 撤销分享
+	Revoke(ctx context.Context, userID, shareID uint) error
+
+	// 流量配额
+	GetTransferStatus(ctx context.Context, code string) (*TransferStatus, error)
+	CheckTransfer(ctx context.Context, code string) (*TransferStatus, error)
+	RecordTransfer(ctx context.Context, shareID uint, bytes int64) error
+}
+
+// ShareStore 分享数据访问，由分享仓储实现
+type ShareStore interface {
+	GetByID(ctx context.Context, id uint) (*models.FileShare, error)
+	GetByCode(ctx context.Context, code string) (*models.FileShare, error)
+	UpdatePassword(ctx context.Context, share *models.FileShare) error
+	UpdateStatus(ctx context.Context, id uint, status string) error
+	IncrementPasswordFailures(ctx context.Context, id uint) (int, error)
+	LockPassword(ctx context.Context, id uint, until time.Time) error
+	ResetPasswordFailures(ctx context.Context, id uint) error
+	ResetTransferPeriod(ctx context.Context, id uint, periodStart time.Time) error
+	AddTransferUsage(ctx context.Context, id uint, bytes int64) error
+}
+
+// SettingReader 读取系统设置，由系统设置仓储实现
+type SettingReader interface {
+	GetSetting(ctx context.Context, category, key string) (*models.SystemSetting, error)
+}
+
+// NotificationWriter 写入站内通知，由通知仓储实现
+type NotificationWriter interface {
+	Create(ctx context.Context, notification *models.Notification) error
+}
+
+// ShareAccess 密码验证通过后返回的分享访问信息
+type ShareAccess struct {
+	ShareID    uint       `json:"share_id"`             // 分享ID
+	ShareCode  string     `json:"share_code"`           // 分享码
+	FileID     uint       `json:"file_id"`              // 分享的文件ID
+	Permission string     `json:"permission"`           // 权限类型
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"` // 过期时间
+}
+
+// PasswordPolicy 分享密码尝试保护策略
+type PasswordPolicy struct {
+	AttemptsPerWindow int           // 同一IP对同一分享每个窗口内允许的尝试次数
+	AttemptWindow     time.Duration // 尝试计数窗口
+	LockThreshold     int           // 累计连续失败多少次后锁定分享
+	LockDuration      time.Duration // 锁定时长
+}
+
+// Policy 分享访问策略：密码保护、流量配额和隐私默认值
+type Policy struct {
+	PasswordPolicy
+	MonthlyTransferLimit int64            // 每个分享每月下载流量上限(字节，0表示不限制)，可被系统设置覆盖
+	TransferLimitPlans   map[string]int64 // 按分享者套餐设置的每月流量上限，优先于系统设置
+	StripImageLocation   bool             // 公开分享下载图片时默认去除位置信息，可被用户和分享设置覆盖
+}
+
+// 分享密码长度限制(字符数)
+const (
+	MinPasswordLength = 4
+	MaxPasswordLength = 32
+)
+
+// DefaultPasswordPolicy 默认密码尝试保护策略
+func DefaultPasswordPolicy() PasswordPolicy {
+	return PasswordPolicy{
+		AttemptsPerWindow: 5,
+		AttemptWindow:     time.Minute,
+		LockThreshold:     20,
+		LockDuration:      15 * time.Minute,
+	}
+}
+
+// PasswordPolicyFromConfig 从分享配置生成策略，未配置的项使用默认值
+func PasswordPolicyFromConfig(cfg config.ShareConfig) PasswordPolicy {
+	policy := DefaultPasswordPolicy()
+	if cfg.PasswordAttemptsPerWindow > 0 {
+		policy.AttemptsPerWindow = cfg.PasswordAttemptsPerWindow
+	}
+	if cfg.PasswordAttemptWindow > 0 {
+		policy.AttemptWindow = cfg.PasswordAttemptWindow
+	}
+	if cfg.PasswordLockThreshold > 0 {
+		policy.LockThreshold = cfg.PasswordLockThreshold
+	}
+	if cfg.PasswordLockDuration > 0 {
+		policy.LockDuration = cfg.PasswordLockDuration
+	}
+	return policy
+}
+
+// DefaultPolicy 默认分享访问策略，不限制流量
+func DefaultPolicy() Policy {
+	return Policy{PasswordPolicy: DefaultPasswordPolicy()}
+}
+
+// PolicyFromConfig 从分享配置生成访问策略，未配置的项使用默认值
+func PolicyFromConfig(cfg config.ShareConfig) Policy {
+	return Policy{
+		PasswordPolicy:       PasswordPolicyFromConfig(cfg),
+		MonthlyTransferLimit: cfg.MonthlyTransferLimit,
+		TransferLimitPlans:   cfg.MonthlyTransferLimitPlans,
+		StripImageLocation:   cfg.StripImageLocation,
+	}
+}
@@ -0,0 +1,278 @@
+package share
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/cache"
+	"cloudpan/internal/pkg/clock"
+	dbmodels "cloudpan/internal/pkg/database/models"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/ratelimit"
+	"cloudpan/internal/repository/models"
+)
+
+// shareService 分享访问保护服务实现
+type shareService struct {
+	shareRepo   ShareStore
+	settingRepo SettingReader
+	owners      TransferOwnerReader
+	notifier    NotificationWriter
+	metadata    MetadataInvalidator
+	limiter     ratelimit.Limiter
+	policy      Policy
+	logger      *zap.Logger
+	clock       clock.Clock
+}
+
+// NewShareService 创建分享访问保护服务实例
+//
+// settingRepo 可以为nil，此时流量上限只取配置值；owners 可以为nil，此时不按分享者套餐解析流量上限；
+// notifier 可以为nil，此时锁定分享时不发送站内通知；
+// metadata 可以为nil，此时分享变更后不清除公开元数据缓存；clk为nil时使用系统时钟
+func NewShareService(shareRepo ShareStore, settingRepo SettingReader, owners TransferOwnerReader, notifier NotificationWriter, metadata MetadataInvalidator, limiter ratelimit.Limiter, policy Policy, clk clock.Clock, logger *zap.Logger) ShareService {
+	return &shareService{
+		shareRepo:   shareRepo,
+		settingRepo: settingRepo,
+		owners:      owners,
+		notifier:    notifier,
+		metadata:    metadata,
+		limiter:     limiter,
+		policy:      policy,
+		logger:      logger,
+		clock:       clock.OrReal(clk),
+	}
+}
+
+// SetPassword 设置或取消分享密码，仅分享者可操作
+//
+// 空密码表示取消密码保护；设置密码会同时清除错误计数和锁定状态
+func (s *shareService) SetPassword(ctx context.Context, userID, shareID uint, password string) error {
+	if password != "" {
+		length := utf8.RuneCountInString(password)
+		if length < MinPasswordLength || length > MaxPasswordLength {
+			return pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "分享密码长度必须在%d到%d个字符之间", MinPasswordLength, MaxPasswordLength)
+		}
+	}
+
+	share, err := s.getShare(ctx, func() (*models.FileShare, error) { return s.shareRepo.GetByID(ctx, shareID) })
+	if err != nil {
+		return err
+	}
+	if share.SharerID != userID {
+		return pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, "只有分享者可以修改分享密码")
+	}
+
+	share.Password = &password
+	share.PasswordFailedAttempts = 0
+	share.PasswordLockedUntil = nil
+	if err := s.shareRepo.UpdatePassword(ctx, share); err != nil {
+		return pkgErrors.WrapError(err, "保存分享密码失败")
+	}
+	s.invalidateMetadata(ctx, share)
+
+	s.logger.Info("分享密码已更新",
+		zap.Uint("share_id", share.ID),
+		zap.Uint("user_id", userID),
+		zap.Bool("has_password", password != ""))
+	return nil
+}
+
+// Revoke 撤销分享，仅分享者可操作
+//
+// 分享被标记为停用状态，之后打开、下载和查询元数据均返回分享已失效；已撤销的分享重复撤销视为成功
+func (s *shareService) Revoke(ctx context.Context, userID, shareID uint) error {
+	share, err := s.getShare(ctx, func() (*models.FileShare, error) { return s.shareRepo.GetByID(ctx, shareID) })
+	if err != nil {
+		return err
+	}
+	if share.SharerID != userID {
+		return pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, "只有分享者可以撤销分享")
+	}
+	if share.Status == "disabled" {
+		return nil
+	}
+
+	if err := s.shareRepo.UpdateStatus(ctx, share.ID, "disabled"); err != nil {
+		return pkgErrors.WrapError(err, "撤销分享失败")
+	}
+	s.invalidateMetadata(ctx, share)
+
+	s.logger.Info("分享已撤销",
+		zap.Uint("share_id", share.ID),
+		zap.Uint("user_id", userID))
+	return nil
+}
+
+// invalidateMetadata 使分享的公开元数据缓存失效，失败只记录日志，缓存随TTL过期
+func (s *shareService) invalidateMetadata(ctx context.Context, share *models.FileShare) {
+	if s.metadata == nil {
+		return
+	}
+	if err := s.metadata.Invalidate(ctx, share.ShareCode); err != nil {
+		s.logger.Warn("清除分享元数据缓存失败", zap.Uint("share_id", share.ID), zap.Error(err))
+	}
+}
+
+// VerifyPassword 验证分享密码
+//
+// 检查顺序：分享可访问 -> 未被锁定 -> 分享+IP未超过尝试频率 -> 密码正确。
+// 密码错误时累计连续错误次数，达到阈值后锁定分享并通知分享者；
+// 验证通过时清除错误计数，历史明文密码会被升级为哈希
+func (s *shareService) VerifyPassword(ctx context.Context, code, password, clientIP string) (*ShareAccess, error) {
+	if code == "" {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "分享码不能为空")
+	}
+
+	share, err := s.getShare(ctx, func() (*models.FileShare, error) { return s.shareRepo.GetByCode(ctx, code) })
+	if err != nil {
+		return nil, err
+	}
+	if !share.IsAccessible() {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "分享已失效")
+	}
+	if !share.HasPassword {
+		return newShareAccess(share), nil
+	}
+
+	now := s.clock.Now()
+	if share.IsPasswordLocked(now) {
+		return nil, lockedError(share.PasswordLockedUntil.Sub(now))
+	}
+
+	limitKey := cache.Keys.RateLimit(clientIP, fmt.Sprintf("share_password:%d", share.ID))
+	result, limitErr := s.limiter.Allow(ctx, limitKey, s.policy.AttemptsPerWindow, s.policy.AttemptWindow)
+	if limitErr != nil {
+		// 限流器不可用时仍由数据库中的连续错误计数兜底
+		s.logger.Warn("分享密码限流检查失败", zap.Uint("share_id", share.ID), zap.Error(limitErr))
+	} else if !result.Allowed {
+		return nil, pkgErrors.WrapErrorf(pkgErrors.ErrTooManyRequests, "密码尝试过于频繁，请%d秒后再试", ceilUnits(result.RetryAfter, time.Second))
+	}
+
+	ok, needsRehash := share.CheckPassword(password)
+	if !ok {
+		return nil, s.recordFailure(ctx, share, clientIP, now)
+	}
+
+	if share.PasswordFailedAttempts > 0 || share.PasswordLockedUntil != nil {
+		if err := s.shareRepo.ResetPasswordFailures(ctx, share.ID); err != nil {
+			s.logger.Warn("清除分享密码错误计数失败", zap.Uint("share_id", share.ID), zap.Error(err))
+		}
+	}
+	if limitErr == nil {
+		if err := s.limiter.Reset(ctx, limitKey); err != nil {
+			s.logger.Warn("清除分享密码限流计数失败", zap.Uint("share_id", share.ID), zap.Error(err))
+		}
+	}
+	if needsRehash {
+		// 明文密码经模型钩子哈希后保存
+		share.Password = &password
+		share.PasswordFailedAttempts = 0
+		share.PasswordLockedUntil = nil
+		if err := s.shareRepo.UpdatePassword(ctx, share); err != nil {
+			s.logger.Warn("升级分享密码哈希失败", zap.Uint("share_id", share.ID), zap.Error(err))
+		}
+	}
+
+	return newShareAccess(share), nil
+}
+
+// recordFailure 累计密码错误次数，达到阈值时锁定分享并通知分享者
+func (s *shareService) recordFailure(ctx context.Context, share *models.FileShare, clientIP string, now time.Time) error {
+	attempts, err := s.shareRepo.IncrementPasswordFailures(ctx, share.ID)
+	if err != nil {
+		return pkgErrors.WrapError(err, "记录分享密码错误次数失败")
+	}
+
+	s.logger.Warn("分享密码错误",
+		zap.Uint("share_id", share.ID),
+		zap.Int("attempts", attempts),
+		zap.String("ip", clientIP))
+
+	if attempts < s.policy.LockThreshold {
+		return pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, "分享密码错误")
+	}
+
+	until := now.Add(s.policy.LockDuration)
+	if err := s.shareRepo.LockPassword(ctx, share.ID, until); err != nil {
+		return pkgErrors.WrapError(err, "锁定分享失败")
+	}
+	s.logger.Warn("分享密码错误次数过多，已临时锁定",
+		zap.Uint("share_id", share.ID),
+		zap.Int("attempts", attempts),
+		zap.Time("locked_until", until))
+
+	s.notifyOwner(ctx, share, clientIP, attempts, until)
+	return lockedError(s.policy.LockDuration)
+}
+
+// notifyOwner 向分享者发送分享被锁定的安全通知，失败只记录日志
+func (s *shareService) notifyOwner(ctx context.Context, share *models.FileShare, clientIP string, attempts int, until time.Time) {
+	if s.notifier == nil {
+		return
+	}
+
+	shareID := share.ID
+	notification := &models.Notification{
+		UserID:      share.SharerID,
+		Type:        models.NotificationTypeSecurityAlert,
+		Title:       "分享链接已被临时锁定",
+		Content:     fmt.Sprintf("您的分享 %s 连续 %d 次密码验证失败，已锁定至 %s。如非本人操作，建议修改分享密码。", share.ShareCode, attempts, until.Format("2006-01-02 15:04:05")),
+		Priority:    "high",
+		RelatedType: "file_share",
+		RelatedID:   &shareID,
+		Data: &dbmodels.JSONMap{
+			"share_code":   share.ShareCode,
+			"ip":           clientIP,
+			"attempts":     attempts,
+			"locked_until": until,
+		},
+	}
+	if err := s.notifier.Create(ctx, notification); err != nil {
+		s.logger.Error("发送分享锁定通知失败", zap.Uint("share_id", share.ID), zap.Error(err))
+	}
+}
+
+// getShare 查询分享并将记录不存在转换为统一错误
+func (s *shareService) getShare(ctx context.Context, load func() (*models.FileShare, error)) (*models.FileShare, error) {
+	share, err := load()
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, pkgErrors.ErrResourceNotFound) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "分享不存在")
+		}
+		return nil, pkgErrors.WrapError(err, "查询分享失败")
+	}
+	return share, nil
+}
+
+// newShareAccess 根据分享生成访问信息
+func newShareAccess(share *models.FileShare) *ShareAccess {
+	return &ShareAccess{
+		ShareID:    share.ID,
+		ShareCode:  share.ShareCode,
+		FileID:     share.FileID,
+		Permission: share.Permission,
+		ExpiresAt:  share.ExpiresAt,
+	}
+}
+
+// lockedError 分享被锁定的错误，提示剩余分钟数
+func lockedError(remaining time.Duration) error {
+	return pkgErrors.WrapErrorf(pkgErrors.ErrTooManyRequests, "分享密码错误次数过多，已临时锁定，请%d分钟后再试", ceilUnits(remaining, time.Minute))
+}
+
+// ceilUnits 将时长向上取整为指定单位的数量，至少为1
+func ceilUnits(d, unit time.Duration) int {
+	n := int(math.Ceil(float64(d) / float64(unit)))
+	if n < 1 {
+		return 1
+	}
+	return n
+}
@@ -0,0 +1,311 @@
+package share
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/clock"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/ratelimit"
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+)
+
+// MockShareStore 模拟分享仓储
+type MockShareStore struct {
+	mock.Mock
+}
+
+func (m *MockShareStore) GetByID(ctx context.Context, id uint) (*models.FileShare, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.FileShare), args.Error(1)
+}
+
+func (m *MockShareStore) GetByCode(ctx context.Context, code string) (*models.FileShare, error) {
+	args := m.Called(ctx, code)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.FileShare), args.Error(1)
+}
+
+func (m *MockShareStore) UpdatePassword(ctx context.Context, share *models.FileShare) error {
+	return m.Called(ctx, share).Error(0)
+}
+
+func (m *MockShareStore) UpdateStatus(ctx context.Context, id uint, status string) error {
+	return m.Called(ctx, id, status).Error(0)
+}
+
+func (m *MockShareStore) IncrementPasswordFailures(ctx context.Context, id uint) (int, error) {
+	args := m.Called(ctx, id)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockShareStore) LockPassword(ctx context.Context, id uint, until time.Time) error {
+	return m.Called(ctx, id, until).Error(0)
+}
+
+func (m *MockShareStore) ResetPasswordFailures(ctx context.Context, id uint) error {
+	return m.Called(ctx, id).Error(0)
+}
+
+func (m *MockShareStore) ResetTransferPeriod(ctx context.Context, id uint, periodStart time.Time) error {
+	return m.Called(ctx, id, periodStart).Error(0)
+}
+
+func (m *MockShareStore) AddTransferUsage(ctx context.Context, id uint, bytes int64) error {
+	return m.Called(ctx, id, bytes).Error(0)
+}
+
+// MockNotificationWriter 模拟通知仓储
+type MockNotificationWriter struct {
+	mock.Mock
+}
+
+func (m *MockNotificationWriter) Create(ctx context.Context, notification *models.Notification) error {
+	return m.Called(ctx, notification).Error(0)
+}
+
+var testNow = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestService(store *MockShareStore, notifier NotificationWriter, policy Policy) *shareService {
+	svc := NewShareService(store, nil, nil, notifier, nil, ratelimit.NewMemoryLimiter(), policy, clock.NewFake(testNow), zap.NewNop()).(*shareService)
+	return svc
+}
+
+func newProtectedShare(t *testing.T, password string) *models.FileShare {
+	hashed, err := utils.HashPassword(password)
+	require.NoError(t, err)
+	share := &models.FileShare{
+		FileID:      10,
+		SharerID:    7,
+		ShareCode:   "abc123",
+		Permission:  "view",
+		Password:    &hashed,
+		HasPassword: true,
+		Status:      "active",
+	}
+	share.ID = 3
+	return share
+}
+
+func TestVerifyPassword_Success(t *testing.T) {
+	ctx := context.Background()
+	store := new(MockShareStore)
+	share := newProtectedShare(t, "secret")
+	share.PasswordFailedAttempts = 2
+	store.On("GetByCode", ctx, "abc123").Return(share, nil)
+	store.On("ResetPasswordFailures", ctx, uint(3)).Return(nil)
+
+	access, err := newTestService(store, nil, DefaultPolicy()).VerifyPassword(ctx, "abc123", "secret", "1.2.3.4")
+	require.NoError(t, err)
+	assert.Equal(t, uint(10), access.FileID)
+	assert.Equal(t, "view", access.Permission)
+	store.AssertExpectations(t)
+}
+
+func TestVerifyPassword_NoPassword(t *testing.T) {
+	ctx := context.Background()
+	store := new(MockShareStore)
+	store.On("GetByCode", ctx, "open").Return(&models.FileShare{ShareCode: "open", Status: "active"}, nil)
+
+	access, err := newTestService(store, nil, DefaultPolicy()).VerifyPassword(ctx, "open", "", "1.2.3.4")
+	require.NoError(t, err)
+	assert.Equal(t, "open", access.ShareCode)
+}
+
+func TestVerifyPassword_NotFoundAndInactive(t *testing.T) {
+	ctx := context.Background()
+	store := new(MockShareStore)
+	store.On("GetByCode", ctx, "missing").Return(nil, gorm.ErrRecordNotFound)
+	store.On("GetByCode", ctx, "disabled").Return(&models.FileShare{Status: "disabled"}, nil)
+	svc := newTestService(store, nil, DefaultPolicy())
+
+	_, err := svc.VerifyPassword(ctx, "missing", "x", "1.2.3.4")
+	assert.True(t, pkgErrors.IsNotFoundError(err))
+
+	_, err = svc.VerifyPassword(ctx, "disabled", "x", "1.2.3.4")
+	assert.True(t, pkgErrors.IsNotFoundError(err))
+}
+
+func TestVerifyPassword_WrongPassword(t *testing.T) {
+	ctx := context.Background()
+	store := new(MockShareStore)
+	store.On("GetByCode", ctx, "abc123").Return(newProtectedShare(t, "secret"), nil)
+	store.On("IncrementPasswordFailures", ctx, uint(3)).Return(1, nil)
+
+	_, err := newTestService(store, nil, DefaultPolicy()).VerifyPassword(ctx, "abc123", "wrong", "1.2.3.4")
+	assert.True(t, pkgErrors.IsPermissionError(err))
+	store.AssertNotCalled(t, "LockPassword", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestVerifyPassword_ThrottlesPerShareAndIP(t *testing.T) {
+	ctx := context.Background()
+	store := new(MockShareStore)
+	store.On("GetByCode", ctx, "abc123").Return(newProtectedShare(t, "secret"), nil)
+	store.On("IncrementPasswordFailures", ctx, uint(3)).Return(1, nil)
+
+	policy := DefaultPolicy()
+	policy.AttemptsPerWindow = 2
+	svc := newTestService(store, nil, policy)
+
+	for i := 0; i < 2; i++ {
+		_, err := svc.VerifyPassword(ctx, "abc123", "wrong", "1.2.3.4")
+		assert.True(t, pkgErrors.IsPermissionError(err))
+	}
+
+	// 超过频率后即使密码正确也被拒绝
+	_, err := svc.VerifyPassword(ctx, "abc123", "secret", "1.2.3.4")
+	assert.True(t, pkgErrors.IsRateLimitError(err))
+
+	// 其他IP不受影响
+	store.On("ResetPasswordFailures", ctx, uint(3)).Return(nil).Maybe()
+	_, err = svc.VerifyPassword(ctx, "abc123", "secret", "5.6.7.8")
+	assert.NoError(t, err)
+	store.AssertNumberOfCalls(t, "IncrementPasswordFailures", 2)
+}
+
+func TestVerifyPassword_LocksAndNotifiesOwner(t *testing.T) {
+	ctx := context.Background()
+	store := new(MockShareStore)
+	notifier := new(MockNotificationWriter)
+	policy := DefaultPolicy()
+	until := testNow.Add(policy.LockDuration)
+
+	store.On("GetByCode", ctx, "abc123").Return(newProtectedShare(t, "secret"), nil)
+	store.On("IncrementPasswordFailures", ctx, uint(3)).Return(policy.LockThreshold, nil)
+	store.On("LockPassword", ctx, uint(3), until).Return(nil)
+	notifier.On("Create", ctx, mock.MatchedBy(func(n *models.Notification) bool {
+		return n.UserID == 7 &&
+			n.Type == models.NotificationTypeSecurityAlert &&
+			n.RelatedType == "file_share" &&
+			*n.RelatedID == 3 &&
+			(*n.Data)["ip"] == "1.2.3.4"
+	})).Return(nil)
+
+	_, err := newTestService(store, notifier, policy).VerifyPassword(ctx, "abc123", "wrong", "1.2.3.4")
+	assert.True(t, pkgErrors.IsRateLimitError(err))
+	store.AssertExpectations(t)
+	notifier.AssertExpectations(t)
+}
+
+func TestVerifyPassword_LockedShare(t *testing.T) {
+	ctx := context.Background()
+	store := new(MockShareStore)
+	share := newProtectedShare(t, "secret")
+	lockedUntil := testNow.Add(90 * time.Second)
+	share.PasswordLockedUntil = &lockedUntil
+	store.On("GetByCode", ctx, "abc123").Return(share, nil)
+
+	_, err := newTestService(store, nil, DefaultPolicy()).VerifyPassword(ctx, "abc123", "secret", "1.2.3.4")
+	assert.True(t, pkgErrors.IsRateLimitError(err))
+	assert.Contains(t, err.Error(), "2分钟")
+	store.AssertNotCalled(t, "IncrementPasswordFailures", mock.Anything, mock.Anything)
+}
+
+func TestVerifyPassword_RehashesLegacyPlaintext(t *testing.T) {
+	ctx := context.Background()
+	store := new(MockShareStore)
+	plain := "legacy"
+	share := &models.FileShare{ShareCode: "old", Password: &plain, HasPassword: true, Status: "active"}
+	share.ID = 4
+	store.On("GetByCode", ctx, "old").Return(share, nil)
+	store.On("UpdatePassword", ctx, share).Return(nil)
+
+	_, err := newTestService(store, nil, DefaultPolicy()).VerifyPassword(ctx, "old", "legacy", "1.2.3.4")
+	require.NoError(t, err)
+	store.AssertCalled(t, "UpdatePassword", ctx, share)
+}
+
+func TestSetPassword(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("owner sets password", func(t *testing.T) {
+		store := new(MockShareStore)
+		share := newProtectedShare(t, "old-secret")
+		lockedUntil := testNow.Add(time.Minute)
+		share.PasswordLockedUntil = &lockedUntil
+		share.PasswordFailedAttempts = 20
+		store.On("GetByID", ctx, uint(3)).Return(share, nil)
+		store.On("UpdatePassword", ctx, mock.MatchedBy(func(s *models.FileShare) bool {
+			return *s.Password == "new-secret" && s.PasswordLockedUntil == nil && s.PasswordFailedAttempts == 0
+		})).Return(nil)
+
+		invalidator := &recordingInvalidator{}
+		svc := newTestService(store, nil, DefaultPolicy())
+		svc.metadata = invalidator
+
+		require.NoError(t, svc.SetPassword(ctx, 7, 3, "new-secret"))
+		store.AssertExpectations(t)
+		assert.Equal(t, []string{"abc123"}, invalidator.codes)
+	})
+
+	t.Run("non owner rejected", func(t *testing.T) {
+		store := new(MockShareStore)
+		store.On("GetByID", ctx, uint(3)).Return(newProtectedShare(t, "secret"), nil)
+
+		err := newTestService(store, nil, DefaultPolicy()).SetPassword(ctx, 8, 3, "new-secret")
+		assert.True(t, pkgErrors.IsPermissionError(err))
+	})
+
+	t.Run("invalid length", func(t *testing.T) {
+		err := newTestService(new(MockShareStore), nil, DefaultPolicy()).SetPassword(ctx, 7, 3, "abc")
+		assert.True(t, pkgErrors.IsValidationError(err))
+	})
+}
+
+// recordingInvalidator 记录失效的分享码
+type recordingInvalidator struct {
+	codes []string
+}
+
+func (r *recordingInvalidator) Invalidate(ctx context.Context, code string) error {
+	r.codes = append(r.codes, code)
+	return nil
+}
+
+func TestRevoke(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("owner revokes and invalidates metadata", func(t *testing.T) {
+		store := new(MockShareStore)
+		store.On("GetByID", ctx, uint(3)).Return(newProtectedShare(t, "secret"), nil)
+		store.On("UpdateStatus", ctx, uint(3), "disabled").Return(nil)
+		invalidator := &recordingInvalidator{}
+		svc := newTestService(store, nil, DefaultPolicy())
+		svc.metadata = invalidator
+
+		require.NoError(t, svc.Revoke(ctx, 7, 3))
+		store.AssertExpectations(t)
+		assert.Equal(t, []string{"abc123"}, invalidator.codes)
+	})
+
+	t.Run("already revoked", func(t *testing.T) {
+		store := new(MockShareStore)
+		share := newProtectedShare(t, "secret")
+		share.Status = "disabled"
+		store.On("GetByID", ctx, uint(3)).Return(share, nil)
+
+		require.NoError(t, newTestService(store, nil, DefaultPolicy()).Revoke(ctx, 7, 3))
+		store.AssertNotCalled(t, "UpdateStatus", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("non owner rejected", func(t *testing.T) {
+		store := new(MockShareStore)
+		store.On("GetByID", ctx, uint(3)).Return(newProtectedShare(t, "secret"), nil)
+
+		err := newTestService(store, nil, DefaultPolicy()).Revoke(ctx, 8, 3)
+		assert.True(t, pkgErrors.IsPermissionError(err))
+	})
+}
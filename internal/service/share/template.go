@@ -0,0 +1,278 @@
+package share
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+)
+
+// 分享模板限制
+const (
+	MaxShareTemplates          = 20 // 每个用户最多保存的模板数
+	MaxShareTemplateNameLength = 30 // 模板名称最大字符数
+)
+
+// 分享模板在用户偏好(PreferenceCategoryShare)中的键
+const (
+	preferenceTemplatePrefix  = "template."        // 模板键前缀，值为模板的JSON
+	PreferenceDefaultTemplate = "default_template" // 默认模板名称
+)
+
+// TemplateService 分享模板服务接口
+//
+// 用户把常用的分享设置(权限、是否要求密码、有效天数、最大访问/下载次数)保存为命名模板，保存在用户偏好中：
+// 1. 创建分享时指定模板名称，请求中未设置的选项取模板的值
+// 2. 未指定模板时使用默认模板，请求 no_template 为true时不应用任何模板
+// 3. 模板要求密码时，创建分享必须提供密码
+//
+// 使用示例：
+//
+//	service := NewTemplateService(userRepo, logger)
+//	_, err := service.Save(ctx, userID, &ShareTemplate{Name: "客户", PasswordRequired: true, ExpireDays: 7, IsDefault: true})
+//	err = service.Apply(ctx, userID, &req)
+type TemplateService interface {
+	List(ctx context.Context, userID uint) ([]*ShareTemplate, error)
+	Save(ctx context.Context, userID uint, template *ShareTemplate) (*ShareTemplate, error)
+	Delete(ctx context.Context, userID uint, name string) error
+	SetDefault(ctx context.Context, userID uint, name string, isDefault bool) error
+	Apply(ctx context.Context, userID uint, req *CreateShareRequest) error
+}
+
+// ShareTemplate 分享模板
+type ShareTemplate struct {
+	Name             string `json:"name"`                   // 模板名称
+	Permission       string `json:"permission,omitempty"`   // 权限类型(view/download)，为空使用系统默认
+	PasswordRequired bool   `json:"password_required"`      // 是否要求设置分享密码
+	ExpireDays       int    `json:"expire_days"`            // 有效天数，0表示永久有效
+	MaxAccess        *int   `json:"max_access,omitempty"`   // 最大访问次数，为空表示不限制
+	MaxDownload      *int   `json:"max_download,omitempty"` // 最大下载次数，为空表示不限制
+	IsDefault        bool   `json:"is_default"`             // 是否为默认模板
+}
+
+// templateService 分享模板服务实现
+type templateService struct {
+	prefs  PreferenceStore
+	logger *zap.Logger
+}
+
+// NewTemplateService 创建分享模板服务
+func NewTemplateService(prefs PreferenceStore, logger *zap.Logger) TemplateService {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &templateService{
+		prefs:  prefs,
+		logger: logger,
+	}
+}
+
+// List 按名称列出用户的分享模板
+func (s *templateService) List(ctx context.Context, userID uint) ([]*ShareTemplate, error) {
+	templates, defaultName, err := s.load(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*ShareTemplate, 0, len(templates))
+	for _, template := range templates {
+		template.IsDefault = template.Name == defaultName
+		result = append(result, template)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+// Save 新建或覆盖同名模板，IsDefault 为true时同时设为默认模板
+func (s *templateService) Save(ctx context.Context, userID uint, template *ShareTemplate) (*ShareTemplate, error) {
+	if template == nil {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "分享模板不能为空")
+	}
+	saved := *template
+	name, err := normalizeTemplateName(saved.Name)
+	if err != nil {
+		return nil, err
+	}
+	saved.Name = name
+	if err := validateShareOptions(saved.Permission, "", saved.ExpireDays, saved.MaxAccess, saved.MaxDownload); err != nil {
+		return nil, err
+	}
+
+	templates, defaultName, err := s.load(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if _, exists := templates[name]; !exists && len(templates) >= MaxShareTemplates {
+		return nil, pkgErrors.WrapErrorf(pkgErrors.ErrOperationNotAllowed, "最多保存%d个分享模板", MaxShareTemplates)
+	}
+
+	isDefault := saved.IsDefault
+	saved.IsDefault = false
+	value, err := json.Marshal(&saved)
+	if err != nil {
+		return nil, fmt.Errorf("序列化分享模板失败: %w", err)
+	}
+	if err := s.prefs.SetUserPreference(ctx, userID, PreferenceCategoryShare, preferenceTemplatePrefix+name, string(value)); err != nil {
+		return nil, fmt.Errorf("保存分享模板失败: %w", err)
+	}
+	if isDefault && defaultName != name {
+		if err := s.prefs.SetUserPreference(ctx, userID, PreferenceCategoryShare, PreferenceDefaultTemplate, name); err != nil {
+			return nil, fmt.Errorf("设置默认分享模板失败: %w", err)
+		}
+		defaultName = name
+	}
+
+	saved.IsDefault = defaultName == name
+	s.logger.Info("Share template saved",
+		zap.Uint("user_id", userID),
+		zap.String("template", name),
+		zap.Bool("default", saved.IsDefault))
+	return &saved, nil
+}
+
+// Delete 删除模板，删除的是默认模板时同时取消默认模板
+func (s *templateService) Delete(ctx context.Context, userID uint, name string) error {
+	templates, defaultName, err := s.load(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if _, exists := templates[name]; !exists {
+		return pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "分享模板不存在")
+	}
+
+	if err := s.prefs.DeleteUserPreference(ctx, userID, PreferenceCategoryShare, preferenceTemplatePrefix+name); err != nil {
+		return fmt.Errorf("删除分享模板失败: %w", err)
+	}
+	if defaultName == name {
+		if err := s.prefs.DeleteUserPreference(ctx, userID, PreferenceCategoryShare, PreferenceDefaultTemplate); err != nil {
+			return fmt.Errorf("取消默认分享模板失败: %w", err)
+		}
+	}
+
+	s.logger.Info("Share template deleted", zap.Uint("user_id", userID), zap.String("template", name))
+	return nil
+}
+
+// SetDefault 设置或取消默认模板，取消的不是当前默认模板时不做修改
+func (s *templateService) SetDefault(ctx context.Context, userID uint, name string, isDefault bool) error {
+	templates, defaultName, err := s.load(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if _, exists := templates[name]; !exists {
+		return pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "分享模板不存在")
+	}
+
+	switch {
+	case isDefault && defaultName != name:
+		err = s.prefs.SetUserPreference(ctx, userID, PreferenceCategoryShare, PreferenceDefaultTemplate, name)
+	case !isDefault && defaultName == name:
+		err = s.prefs.DeleteUserPreference(ctx, userID, PreferenceCategoryShare, PreferenceDefaultTemplate)
+	}
+	if err != nil {
+		return fmt.Errorf("设置默认分享模板失败: %w", err)
+	}
+	return nil
+}
+
+// Apply 将模板应用到创建分享请求，请求中已设置的选项优先
+//
+// 请求指定了模板名称时使用该模板，否则使用默认模板；没有默认模板或 NoTemplate 为true时不做修改
+func (s *templateService) Apply(ctx context.Context, userID uint, req *CreateShareRequest) error {
+	if req == nil || (req.NoTemplate && req.Template == "") {
+		return nil
+	}
+	templates, defaultName, err := s.load(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	name := req.Template
+	if name == "" {
+		name = defaultName
+	}
+	if name == "" {
+		return nil
+	}
+	template, ok := templates[name]
+	if !ok {
+		if req.Template == "" {
+			// 默认模板已被删除，忽略残留的默认设置
+			return nil
+		}
+		return pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "分享模板不存在")
+	}
+
+	if req.Permission == "" {
+		req.Permission = template.Permission
+	}
+	if req.ExpireDays == 0 {
+		req.ExpireDays = template.ExpireDays
+	}
+	if req.MaxAccess == nil {
+		req.MaxAccess = template.MaxAccess
+	}
+	if req.MaxDownload == nil {
+		req.MaxDownload = template.MaxDownload
+	}
+	if template.PasswordRequired && req.Password == "" {
+		return pkgErrors.WrapErrorf(pkgErrors.ErrMissingRequired, "分享模板「%s」要求设置分享密码", name)
+	}
+	return nil
+}
+
+// load 读取用户的全部模板和默认模板名称，无法解析的模板跳过
+func (s *templateService) load(ctx context.Context, userID uint) (map[string]*ShareTemplate, string, error) {
+	if userID == 0 {
+		return nil, "", pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "用户ID不能为空")
+	}
+	preferences, err := s.prefs.GetUserPreferences(ctx, userID, PreferenceCategoryShare)
+	if err != nil {
+		return nil, "", fmt.Errorf("获取分享模板失败: %w", err)
+	}
+
+	templates := make(map[string]*ShareTemplate)
+	var defaultName string
+	for _, preference := range preferences {
+		if preference.Value == nil {
+			continue
+		}
+		if preference.Key == PreferenceDefaultTemplate {
+			defaultName = *preference.Value
+			continue
+		}
+		name, ok := strings.CutPrefix(preference.Key, preferenceTemplatePrefix)
+		if !ok {
+			continue
+		}
+		var template ShareTemplate
+		if err := json.Unmarshal([]byte(*preference.Value), &template); err != nil {
+			s.logger.Warn("Skipping invalid share template",
+				zap.Uint("user_id", userID),
+				zap.String("template", name),
+				zap.Error(err))
+			continue
+		}
+		template.Name = name
+		templates[name] = &template
+	}
+	return templates, defaultName, nil
+}
+
+// normalizeTemplateName 去除首尾空白并校验模板名称
+func normalizeTemplateName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "分享模板名称不能为空")
+	}
+	if utf8.RuneCountInString(name) > MaxShareTemplateNameLength {
+		return "", pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "分享模板名称不能超过%d个字符", MaxShareTemplateNameLength)
+	}
+	return name, nil
+}
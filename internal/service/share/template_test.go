@@ -0,0 +1,139 @@
+package share
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+)
+
+func newTemplateFixture() (*templateService, *memoryPreferences) {
+	prefs := &memoryPreferences{values: map[string]string{}}
+	return NewTemplateService(prefs, nil).(*templateService), prefs
+}
+
+func TestTemplateService_SaveAndList(t *testing.T) {
+	ctx := context.Background()
+	service, prefs := newTemplateFixture()
+	limit := 5
+
+	saved, err := service.Save(ctx, 7, &ShareTemplate{Name: " 客户 ", PasswordRequired: true, ExpireDays: 7, MaxDownload: &limit, IsDefault: true})
+	require.NoError(t, err)
+	assert.Equal(t, "客户", saved.Name)
+	assert.True(t, saved.IsDefault)
+	assert.Equal(t, "客户", prefs.values[PreferenceDefaultTemplate])
+
+	_, err = service.Save(ctx, 7, &ShareTemplate{Name: "公开", Permission: "view"})
+	require.NoError(t, err)
+	// 无法解析的模板跳过
+	prefs.values[preferenceTemplatePrefix+"损坏"] = "{"
+
+	templates, err := service.List(ctx, 7)
+	require.NoError(t, err)
+	require.Len(t, templates, 2)
+	assert.Equal(t, "公开", templates[0].Name)
+	assert.False(t, templates[0].IsDefault)
+	assert.Equal(t, "客户", templates[1].Name)
+	assert.True(t, templates[1].IsDefault)
+	assert.Equal(t, 5, *templates[1].MaxDownload)
+
+	t.Run("invalid options", func(t *testing.T) {
+		_, err := service.Save(ctx, 7, &ShareTemplate{Name: "x", Permission: "edit"})
+		assert.ErrorIs(t, err, pkgErrors.ErrInvalidInput)
+		_, err = service.Save(ctx, 7, &ShareTemplate{Name: "x", ExpireDays: MaxExpireDays + 1})
+		assert.ErrorIs(t, err, pkgErrors.ErrInvalidInput)
+		_, err = service.Save(ctx, 7, &ShareTemplate{Name: "  "})
+		assert.ErrorIs(t, err, pkgErrors.ErrMissingRequired)
+	})
+}
+
+func TestTemplateService_SaveLimit(t *testing.T) {
+	ctx := context.Background()
+	service, prefs := newTemplateFixture()
+	for i := 0; i < MaxShareTemplates; i++ {
+		prefs.values[preferenceTemplatePrefix+string(rune('a'+i))] = "{}"
+	}
+
+	_, err := service.Save(ctx, 7, &ShareTemplate{Name: "new"})
+	assert.True(t, pkgErrors.IsPermissionError(err))
+	_, err = service.Save(ctx, 7, &ShareTemplate{Name: "a", ExpireDays: 3})
+	assert.NoError(t, err, "覆盖已有模板不受数量限制")
+}
+
+func TestTemplateService_DeleteAndDefault(t *testing.T) {
+	ctx := context.Background()
+	service, prefs := newTemplateFixture()
+	_, err := service.Save(ctx, 7, &ShareTemplate{Name: "a"})
+	require.NoError(t, err)
+	_, err = service.Save(ctx, 7, &ShareTemplate{Name: "b"})
+	require.NoError(t, err)
+
+	require.NoError(t, service.SetDefault(ctx, 7, "a", true))
+	assert.Equal(t, "a", prefs.values[PreferenceDefaultTemplate])
+	require.NoError(t, service.SetDefault(ctx, 7, "b", false), "取消非默认模板不做修改")
+	assert.Equal(t, "a", prefs.values[PreferenceDefaultTemplate])
+	assert.ErrorIs(t, service.SetDefault(ctx, 7, "c", true), pkgErrors.ErrResourceNotFound)
+
+	require.NoError(t, service.Delete(ctx, 7, "a"))
+	assert.NotContains(t, prefs.values, PreferenceDefaultTemplate, "删除默认模板时取消默认")
+	assert.NotContains(t, prefs.values, preferenceTemplatePrefix+"a")
+	assert.ErrorIs(t, service.Delete(ctx, 7, "a"), pkgErrors.ErrResourceNotFound)
+}
+
+func TestTemplateService_Apply(t *testing.T) {
+	ctx := context.Background()
+	service, prefs := newTemplateFixture()
+	limit := 3
+	_, err := service.Save(ctx, 7, &ShareTemplate{Name: "客户", Permission: "view", PasswordRequired: true, ExpireDays: 7, MaxDownload: &limit, IsDefault: true})
+	require.NoError(t, err)
+	_, err = service.Save(ctx, 7, &ShareTemplate{Name: "长期", ExpireDays: 30})
+	require.NoError(t, err)
+
+	t.Run("default template", func(t *testing.T) {
+		req := &CreateShareRequest{FileID: 1, Password: "1234"}
+		require.NoError(t, service.Apply(ctx, 7, req))
+		assert.Equal(t, "view", req.Permission)
+		assert.Equal(t, 7, req.ExpireDays)
+		assert.Equal(t, 3, *req.MaxDownload)
+		assert.Nil(t, req.MaxAccess)
+	})
+
+	t.Run("request overrides template", func(t *testing.T) {
+		other := 10
+		req := &CreateShareRequest{FileID: 1, Password: "1234", Permission: "download", ExpireDays: 1, MaxDownload: &other}
+		require.NoError(t, service.Apply(ctx, 7, req))
+		assert.Equal(t, "download", req.Permission)
+		assert.Equal(t, 1, req.ExpireDays)
+		assert.Equal(t, 10, *req.MaxDownload)
+	})
+
+	t.Run("password required", func(t *testing.T) {
+		err := service.Apply(ctx, 7, &CreateShareRequest{FileID: 1})
+		assert.ErrorIs(t, err, pkgErrors.ErrMissingRequired)
+	})
+
+	t.Run("named template", func(t *testing.T) {
+		req := &CreateShareRequest{FileID: 1, Template: "长期", NoTemplate: true}
+		require.NoError(t, service.Apply(ctx, 7, req))
+		assert.Equal(t, 30, req.ExpireDays)
+
+		err := service.Apply(ctx, 7, &CreateShareRequest{FileID: 1, Template: "missing"})
+		assert.ErrorIs(t, err, pkgErrors.ErrResourceNotFound)
+	})
+
+	t.Run("no template", func(t *testing.T) {
+		req := &CreateShareRequest{FileID: 1, NoTemplate: true}
+		require.NoError(t, service.Apply(ctx, 7, req))
+		assert.Zero(t, req.ExpireDays)
+	})
+
+	t.Run("stale default", func(t *testing.T) {
+		prefs.values[PreferenceDefaultTemplate] = "已删除"
+		req := &CreateShareRequest{FileID: 1}
+		require.NoError(t, service.Apply(ctx, 7, req))
+		assert.Empty(t, req.Permission)
+	})
+}
@@ -0,0 +1,158 @@
+package share
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/repository/models"
+	"cloudpan/internal/service/featureflag"
+)
+
+// TransferOwnerReader 读取分享者的套餐，由用户仓储实现
+type TransferOwnerReader interface {
+	GetByID(ctx context.Context, id uint) (*models.User, error)
+}
+
+// TransferStatus 分享当前统计周期的流量使用情况
+type TransferStatus struct {
+	ShareID     uint      `json:"share_id"`     // 分享ID
+	Limit       int64     `json:"limit"`        // 本周期流量上限(字节，0表示不限制)
+	Used        int64     `json:"used"`         // 本周期已消耗流量
+	Remaining   int64     `json:"remaining"`    // 剩余流量(不限制时为-1)
+	Exhausted   bool      `json:"exhausted"`    // 流量是否已用尽
+	PeriodStart time.Time `json:"period_start"` // 统计周期开始时间
+	ResetsAt    time.Time `json:"resets_at"`    // 流量恢复时间
+}
+
+// TransferLimitError 分享流量已用尽错误
+//
+// 携带流量状态，处理器据此返回专用错误码和恢复时间，客户端可展示友好的提示页
+type TransferLimitError struct {
+	Status *TransferStatus
+}
+
+// Error 实现error接口
+func (e *TransferLimitError) Error() string {
+	return fmt.Sprintf("该分享本月下载流量已用尽，将于%s恢复", e.Status.ResetsAt.Format("2006-01-02"))
+}
+
+// Unwrap 流量用尽属于配额超出
+func (e *TransferLimitError) Unwrap() error {
+	return pkgErrors.ErrQuotaExceeded
+}
+
+// GetTransferStatus 查询分享当前周期的流量使用情况，流量用尽不视为错误
+func (s *shareService) GetTransferStatus(ctx context.Context, code string) (*TransferStatus, error) {
+	if code == "" {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "分享码不能为空")
+	}
+
+	share, err := s.getShare(ctx, func() (*models.FileShare, error) { return s.shareRepo.GetByCode(ctx, code) })
+	if err != nil {
+		return nil, err
+	}
+	if !share.IsAccessible() {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "分享已失效")
+	}
+
+	now := s.clock.Now()
+	periodStart := models.TransferPeriodStart(now)
+	if share.TransferPeriodStart == nil || share.TransferPeriodStart.Before(periodStart) {
+		// 进入新的统计周期，清零上个周期的流量
+		if err := s.shareRepo.ResetTransferPeriod(ctx, share.ID, periodStart); err != nil {
+			return nil, pkgErrors.WrapError(err, "重置分享流量统计失败")
+		}
+	}
+
+	limit := s.transferLimit(ctx, share.SharerID)
+	used := share.CurrentTransferUsed(now)
+	status := &TransferStatus{
+		ShareID:     share.ID,
+		Limit:       limit,
+		Used:        used,
+		Remaining:   -1,
+		PeriodStart: periodStart,
+		ResetsAt:    periodStart.AddDate(0, 1, 0),
+	}
+	if limit > 0 {
+		status.Remaining = limit - used
+		if status.Remaining <= 0 {
+			status.Remaining = 0
+			status.Exhausted = true
+		}
+	}
+	return status, nil
+}
+
+// CheckTransfer 下载前检查分享流量，流量已用尽时返回 *TransferLimitError
+//
+// 配额为软限制：只要本周期流量尚未用尽，本次下载允许完整传输，超出部分计入本周期
+func (s *shareService) CheckTransfer(ctx context.Context, code string) (*TransferStatus, error) {
+	status, err := s.GetTransferStatus(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	if status.Exhausted {
+		s.logger.Info("分享下载流量已用尽",
+			zap.Uint("share_id", status.ShareID),
+			zap.Int64("limit", status.Limit),
+			zap.Int64("used", status.Used))
+		return status, &TransferLimitError{Status: status}
+	}
+	return status, nil
+}
+
+// RecordTransfer 累计分享实际传输的字节数，下载结束(包括中断)后调用
+func (s *shareService) RecordTransfer(ctx context.Context, shareID uint, bytes int64) error {
+	if bytes <= 0 {
+		return nil
+	}
+	if err := s.shareRepo.AddTransferUsage(ctx, shareID, bytes); err != nil {
+		return pkgErrors.WrapError(err, "记录分享流量失败")
+	}
+	return nil
+}
+
+// transferLimit 解析分享每月流量上限：分享者套餐 -> 系统设置 -> 配置文件
+func (s *shareService) transferLimit(ctx context.Context, sharerID uint) int64 {
+	if limit, ok := s.planTransferLimit(ctx, sharerID); ok {
+		return limit
+	}
+	if s.settingRepo != nil {
+		setting, err := s.settingRepo.GetSetting(ctx, models.SettingCategoryStorage, models.SettingKeyShareTransferLimit)
+		if err == nil && setting != nil {
+			value := strings.TrimSpace(setting.GetStringValue())
+			if limit, err := strconv.ParseInt(value, 10, 64); err == nil && limit >= 0 {
+				return limit
+			}
+			s.logger.Warn("分享流量上限设置格式错误", zap.String("value", value))
+		} else if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			s.logger.Warn("读取分享流量上限设置失败", zap.Error(err))
+		}
+	}
+	return s.policy.MonthlyTransferLimit
+}
+
+// planTransferLimit 按分享者资料中的套餐查找流量上限，未配置套餐上限或读取分享者失败时返回false
+func (s *shareService) planTransferLimit(ctx context.Context, sharerID uint) (int64, bool) {
+	if s.owners == nil || len(s.policy.TransferLimitPlans) == 0 {
+		return 0, false
+	}
+	owner, err := s.owners.GetByID(ctx, sharerID)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			s.logger.Warn("读取分享者套餐失败", zap.Uint("sharer_id", sharerID), zap.Error(err))
+		}
+		return 0, false
+	}
+	limit, ok := s.policy.TransferLimitPlans[strings.ToLower(featureflag.SubjectFromUser(owner).Plan)]
+	return limit, ok
+}
@@ -0,0 +1,180 @@
+package share
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/clock"
+	basemodels "cloudpan/internal/pkg/database/models"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/ratelimit"
+	"cloudpan/internal/repository/models"
+)
+
+// MockSettingReader 模拟系统设置读取
+type MockSettingReader struct {
+	mock.Mock
+}
+
+func (m *MockSettingReader) GetSetting(ctx context.Context, category, key string) (*models.SystemSetting, error) {
+	args := m.Called(ctx, category, key)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.SystemSetting), args.Error(1)
+}
+
+const gigabyte int64 = 1 << 30
+
+func newTransferService(store *MockShareStore, settings SettingReader, limit int64) *shareService {
+	policy := DefaultPolicy()
+	policy.MonthlyTransferLimit = limit
+	svc := NewShareService(store, settings, nil, nil, nil, ratelimit.NewMemoryLimiter(), policy, clock.NewFake(testNow), zap.NewNop()).(*shareService)
+	return svc
+}
+
+func newTransferShare(used int64, periodStart time.Time) *models.FileShare {
+	share := &models.FileShare{ShareCode: "abc123", Status: "active", TransferUsed: used, TransferPeriodStart: &periodStart}
+	share.ID = 3
+	return share
+}
+
+func TestGetTransferStatus_CurrentPeriod(t *testing.T) {
+	ctx := context.Background()
+	store := new(MockShareStore)
+	periodStart := models.TransferPeriodStart(testNow)
+	store.On("GetByCode", ctx, "abc123").Return(newTransferShare(4*gigabyte, periodStart), nil)
+
+	status, err := newTransferService(store, nil, 10*gigabyte).GetTransferStatus(ctx, "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, 6*gigabyte, status.Remaining)
+	assert.False(t, status.Exhausted)
+	assert.Equal(t, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), status.ResetsAt)
+	store.AssertNotCalled(t, "ResetTransferPeriod", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGetTransferStatus_NewPeriodResetsUsage(t *testing.T) {
+	ctx := context.Background()
+	store := new(MockShareStore)
+	lastMonth := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	store.On("GetByCode", ctx, "abc123").Return(newTransferShare(10*gigabyte, lastMonth), nil)
+	store.On("ResetTransferPeriod", ctx, uint(3), models.TransferPeriodStart(testNow)).Return(nil)
+
+	status, err := newTransferService(store, nil, 10*gigabyte).GetTransferStatus(ctx, "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), status.Used)
+	assert.False(t, status.Exhausted)
+	store.AssertExpectations(t)
+}
+
+func TestGetTransferStatus_Unlimited(t *testing.T) {
+	ctx := context.Background()
+	store := new(MockShareStore)
+	store.On("GetByCode", ctx, "abc123").Return(newTransferShare(100*gigabyte, models.TransferPeriodStart(testNow)), nil)
+
+	status, err := newTransferService(store, nil, 0).GetTransferStatus(ctx, "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, int64(-1), status.Remaining)
+	assert.False(t, status.Exhausted)
+}
+
+func TestGetTransferStatus_SettingOverridesConfig(t *testing.T) {
+	ctx := context.Background()
+	store := new(MockShareStore)
+	settings := new(MockSettingReader)
+	store.On("GetByCode", ctx, "abc123").Return(newTransferShare(2*gigabyte, models.TransferPeriodStart(testNow)), nil)
+
+	value := "1073741824"
+	settings.On("GetSetting", ctx, models.SettingCategoryStorage, models.SettingKeyShareTransferLimit).
+		Return(&models.SystemSetting{Value: &value}, nil)
+
+	status, err := newTransferService(store, settings, 10*gigabyte).GetTransferStatus(ctx, "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, gigabyte, status.Limit)
+	assert.True(t, status.Exhausted)
+}
+
+func TestGetTransferStatus_MissingSettingFallsBackToConfig(t *testing.T) {
+	ctx := context.Background()
+	store := new(MockShareStore)
+	settings := new(MockSettingReader)
+	store.On("GetByCode", ctx, "abc123").Return(newTransferShare(0, models.TransferPeriodStart(testNow)), nil)
+	settings.On("GetSetting", ctx, mock.Anything, mock.Anything).Return(nil, gorm.ErrRecordNotFound)
+
+	status, err := newTransferService(store, settings, 10*gigabyte).GetTransferStatus(ctx, "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, 10*gigabyte, status.Limit)
+}
+
+func TestGetTransferStatus_LimitByPlan(t *testing.T) {
+	ctx := context.Background()
+	store := new(MockShareStore)
+	settings := new(MockSettingReader)
+	value := "5368709120"
+	settings.On("GetSetting", ctx, models.SettingCategoryStorage, models.SettingKeyShareTransferLimit).
+		Return(&models.SystemSetting{Value: &value}, nil)
+
+	owners := memoryOwners{
+		7: {Profile: &basemodels.JSONMap{"plan": "Pro"}},
+		8: {},
+		9: {Profile: &basemodels.JSONMap{"plan": "enterprise"}},
+	}
+	for code, sharerID := range map[string]uint{"pro": 7, "free": 8, "other": 9} {
+		share := newTransferShare(2*gigabyte, models.TransferPeriodStart(testNow))
+		share.ShareCode = code
+		share.SharerID = sharerID
+		store.On("GetByCode", ctx, code).Return(share, nil)
+	}
+
+	svc := newTransferService(store, settings, 10*gigabyte)
+	svc.owners = owners
+	svc.policy.TransferLimitPlans = map[string]int64{"pro": 100 * gigabyte, "free": gigabyte}
+
+	pro, err := svc.GetTransferStatus(ctx, "pro")
+	require.NoError(t, err)
+	assert.Equal(t, 100*gigabyte, pro.Limit)
+	assert.False(t, pro.Exhausted)
+
+	free, err := svc.CheckTransfer(ctx, "free")
+	require.Error(t, err, "未设置套餐的分享者按免费套餐计算")
+	assert.Equal(t, gigabyte, free.Limit)
+	assert.True(t, free.Exhausted)
+
+	other, err := svc.GetTransferStatus(ctx, "other")
+	require.NoError(t, err)
+	assert.Equal(t, 5*gigabyte, other.Limit, "未配置的套餐使用系统设置")
+}
+
+func TestCheckTransfer_Exhausted(t *testing.T) {
+	ctx := context.Background()
+	store := new(MockShareStore)
+	store.On("GetByCode", ctx, "abc123").Return(newTransferShare(11*gigabyte, models.TransferPeriodStart(testNow)), nil)
+
+	status, err := newTransferService(store, nil, 10*gigabyte).CheckTransfer(ctx, "abc123")
+	require.Error(t, err)
+	assert.True(t, status.Exhausted)
+	assert.ErrorIs(t, err, pkgErrors.ErrQuotaExceeded)
+
+	var limitErr *TransferLimitError
+	require.True(t, errors.As(err, &limitErr))
+	assert.Contains(t, limitErr.Error(), "2024-02-01")
+}
+
+func TestRecordTransfer(t *testing.T) {
+	ctx := context.Background()
+	store := new(MockShareStore)
+	store.On("AddTransferUsage", ctx, uint(3), int64(4096)).Return(nil)
+	svc := newTransferService(store, nil, 10*gigabyte)
+
+	require.NoError(t, svc.RecordTransfer(ctx, 3, 4096))
+	require.NoError(t, svc.RecordTransfer(ctx, 3, 0))
+	store.AssertNumberOfCalls(t, "AddTransferUsage", 1)
+}
@@ -0,0 +1,326 @@
+package sso
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	basemodels "cloudpan/internal/pkg/database/models"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+)
+
+// 域名验证记录
+const (
+	VerificationRecordPrefix = "_cloudpan-sso."             // TXT记录名前缀
+	VerificationValuePrefix  = "cloudpan-sso-verification=" // TXT记录值前缀
+	verificationTokenLength  = 32                           // 验证值长度(十六进制字符)
+)
+
+var (
+	tenantPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{1,99}$`)
+	domainPattern = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z]{2,63}$`)
+)
+
+// ListProviders 列出全部身份提供方
+func (s *service) ListProviders(ctx context.Context) ([]*models.SSOProvider, error) {
+	return s.repo.ListProviders(ctx)
+}
+
+// GetProvider 获取身份提供方
+func (s *service) GetProvider(ctx context.Context, id uint) (*models.SSOProvider, error) {
+	provider, err := s.repo.GetProvider(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "身份提供方不存在")
+		}
+		return nil, pkgErrors.WrapError(err, "获取身份提供方失败")
+	}
+	return provider, nil
+}
+
+// CreateProvider 创建身份提供方，保存前获取发现文档确认签发者地址可用
+func (s *service) CreateProvider(ctx context.Context, req *ProviderRequest) (*models.SSOProvider, error) {
+	tenant := strings.ToLower(strings.TrimSpace(req.Tenant))
+	if !tenantPattern.MatchString(tenant) {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "租户标识只能包含小写字母、数字、下划线和连字符(2-100个字符)")
+	}
+	if _, err := s.repo.GetProviderByTenant(ctx, tenant); err == nil {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrResourceExists, "该租户已配置身份提供方")
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, pkgErrors.WrapError(err, "查询身份提供方失败")
+	}
+
+	provider := &models.SSOProvider{
+		Tenant:     tenant,
+		JITEnabled: true,
+		IsActive:   true,
+	}
+	if err := s.applyRequest(ctx, provider, req); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateProvider(ctx, provider); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("SSO provider created",
+		zap.Uint("provider_id", provider.ID),
+		zap.String("tenant", provider.Tenant),
+		zap.String("issuer", provider.Issuer))
+	return provider, nil
+}
+
+// UpdateProvider 更新身份提供方，租户标识不可修改
+func (s *service) UpdateProvider(ctx context.Context, id uint, req *ProviderRequest) (*models.SSOProvider, error) {
+	provider, err := s.GetProvider(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if req.Tenant != "" && !strings.EqualFold(strings.TrimSpace(req.Tenant), provider.Tenant) {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "租户标识创建后不可修改")
+	}
+	if err := s.applyRequest(ctx, provider, req); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateProvider(ctx, provider); err != nil {
+		return nil, err
+	}
+	s.forgetProvider(provider.ID)
+
+	s.logger.Info("SSO provider updated",
+		zap.Uint("provider_id", provider.ID),
+		zap.String("tenant", provider.Tenant),
+		zap.Bool("is_active", provider.IsActive),
+		zap.Bool("enforce_sso", provider.EnforceSSO))
+	return provider, nil
+}
+
+// DeleteProvider 删除身份提供方，认领的域名随之释放
+func (s *service) DeleteProvider(ctx context.Context, id uint) error {
+	provider, err := s.GetProvider(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.DeleteProvider(ctx, provider.ID); err != nil {
+		return err
+	}
+	s.forgetProvider(provider.ID)
+
+	s.logger.Info("SSO provider deleted",
+		zap.Uint("provider_id", provider.ID),
+		zap.String("tenant", provider.Tenant))
+	return nil
+}
+
+// AddDomain 为身份提供方登记邮箱域名，返回验证需要添加的TXT记录
+//
+// 每个域名只能被一个身份提供方认领
+func (s *service) AddDomain(ctx context.Context, providerID uint, domain string) (*DomainInfo, error) {
+	provider, err := s.GetProvider(ctx, providerID)
+	if err != nil {
+		return nil, err
+	}
+	domain = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+	if len(domain) > 255 || !domainPattern.MatchString(domain) {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrInvalidFormat, "域名格式不正确")
+	}
+	if _, err := s.repo.GetDomain(ctx, domain); err == nil {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrResourceExists, "该域名已被认领")
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, pkgErrors.WrapError(err, "查询域名失败")
+	}
+
+	token, err := utils.GenerateHex(verificationTokenLength)
+	if err != nil {
+		return nil, pkgErrors.WrapError(err, "生成验证值失败")
+	}
+	record := &models.SSODomain{
+		ProviderID:        provider.ID,
+		Domain:            domain,
+		VerificationToken: token,
+	}
+	if err := s.repo.CreateDomain(ctx, record); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("SSO domain claimed",
+		zap.Uint("provider_id", provider.ID),
+		zap.String("domain", domain))
+	return newDomainInfo(record), nil
+}
+
+// VerifyDomain 查询域名的TXT记录，包含验证值时标记为已验证
+func (s *service) VerifyDomain(ctx context.Context, providerID, domainID uint) (*DomainInfo, error) {
+	record, err := s.providerDomain(ctx, providerID, domainID)
+	if err != nil {
+		return nil, err
+	}
+	if record.IsVerified() {
+		return newDomainInfo(record), nil
+	}
+
+	info := newDomainInfo(record)
+	values, err := s.resolver.LookupTXT(ctx, info.RecordName)
+	if err != nil {
+		s.logger.Info("SSO domain verification lookup failed",
+			zap.String("domain", record.Domain),
+			zap.Error(err))
+		return nil, pkgErrors.WrapErrorf(pkgErrors.ErrValidationFailed, "未找到TXT记录 %s", info.RecordName)
+	}
+	found := false
+	for _, value := range values {
+		if strings.TrimSpace(value) == info.RecordValue {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, pkgErrors.WrapErrorf(pkgErrors.ErrValidationFailed, "TXT记录 %s 的值不正确", info.RecordName)
+	}
+
+	now := s.now()
+	if err := s.repo.MarkDomainVerified(ctx, record.ID, now); err != nil {
+		return nil, err
+	}
+	record.VerifiedAt = &now
+
+	s.logger.Info("SSO domain verified",
+		zap.Uint("provider_id", record.ProviderID),
+		zap.String("domain", record.Domain))
+	return info, nil
+}
+
+// DeleteDomain 删除域名认领
+func (s *service) DeleteDomain(ctx context.Context, providerID, domainID uint) error {
+	record, err := s.providerDomain(ctx, providerID, domainID)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.DeleteDomain(ctx, record.ID); err != nil {
+		return err
+	}
+
+	s.logger.Info("SSO domain released",
+		zap.Uint("provider_id", record.ProviderID),
+		zap.String("domain", record.Domain))
+	return nil
+}
+
+// providerDomain 获取属于身份提供方的域名
+func (s *service) providerDomain(ctx context.Context, providerID, domainID uint) (*models.SSODomain, error) {
+	record, err := s.repo.GetDomainByID(ctx, domainID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "域名不存在")
+		}
+		return nil, pkgErrors.WrapError(err, "获取域名失败")
+	}
+	if record.ProviderID != providerID {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "域名不存在")
+	}
+	return record, nil
+}
+
+// applyRequest 校验请求并写入身份提供方，签发者或客户端变更时重新获取发现文档
+func (s *service) applyRequest(ctx context.Context, provider *models.SSOProvider, req *ProviderRequest) error {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len([]rune(name)) > 100 {
+		return pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "名称不能为空且不能超过100个字符")
+	}
+	issuer := strings.TrimSuffix(strings.TrimSpace(req.Issuer), "/")
+	if err := validateIssuer(issuer); err != nil {
+		return err
+	}
+	clientID := strings.TrimSpace(req.ClientID)
+	if clientID == "" {
+		return pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "客户端ID不能为空")
+	}
+
+	defaultRole := req.DefaultRole
+	if defaultRole == "" {
+		defaultRole = models.SSORoles[0]
+	}
+	if !models.IsSSORole(defaultRole) {
+		return pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "默认角色只能是 %s", strings.Join(models.SSORoles, "/"))
+	}
+	var mappings *basemodels.JSONMap
+	if len(req.RoleMappings) > 0 {
+		m := make(basemodels.JSONMap, len(req.RoleMappings))
+		for group, role := range req.RoleMappings {
+			if strings.TrimSpace(group) == "" || !models.IsSSORole(role) {
+				return pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "组 %q 的角色只能是 %s", group, strings.Join(models.SSORoles, "/"))
+			}
+			m[group] = role
+		}
+		mappings = &m
+	}
+
+	groupsClaim := strings.TrimSpace(req.GroupsClaim)
+	if groupsClaim == "" {
+		groupsClaim = "groups"
+	}
+
+	// 签发者变更时确认新地址可用，避免保存后所有用户无法登录
+	if issuer != provider.Issuer {
+		if _, err := s.discover(ctx, issuer); err != nil {
+			s.logger.Info("SSO provider discovery failed",
+				zap.String("issuer", issuer),
+				zap.Error(err))
+			return pkgErrors.WrapErrorf(pkgErrors.ErrValidationFailed, "无法获取签发者 %s 的发现文档", issuer)
+		}
+	}
+
+	provider.Name = name
+	provider.Issuer = issuer
+	provider.ClientID = clientID
+	if req.ClientSecret != nil {
+		provider.ClientSecret = strings.TrimSpace(*req.ClientSecret)
+	}
+	provider.Scopes = strings.Join(strings.Fields(req.Scopes), " ")
+	provider.GroupsClaim = groupsClaim
+	provider.RoleMappings = mappings
+	provider.DefaultRole = defaultRole
+	if req.JITEnabled != nil {
+		provider.JITEnabled = *req.JITEnabled
+	}
+	if req.EnforceSSO != nil {
+		provider.EnforceSSO = *req.EnforceSSO
+	}
+	if req.IsActive != nil {
+		provider.IsActive = *req.IsActive
+	}
+	return nil
+}
+
+// validateIssuer 签发者必须是https地址，本机地址允许http用于开发环境
+func validateIssuer(issuer string) error {
+	parsed, err := url.Parse(issuer)
+	if err != nil || parsed.Host == "" || parsed.RawQuery != "" || parsed.Fragment != "" {
+		return pkgErrors.WrapError(pkgErrors.ErrInvalidFormat, "签发者地址格式不正确")
+	}
+	switch parsed.Scheme {
+	case "https":
+		return nil
+	case "http":
+		switch parsed.Hostname() {
+		case "localhost", "127.0.0.1", "::1":
+			return nil
+		}
+	}
+	return pkgErrors.WrapError(pkgErrors.ErrInvalidFormat, "签发者地址必须使用https")
+}
+
+// newDomainInfo 附加验证需要添加的TXT记录
+func newDomainInfo(record *models.SSODomain) *DomainInfo {
+	return &DomainInfo{
+		SSODomain:   record,
+		RecordName:  VerificationRecordPrefix + record.Domain,
+		RecordValue: VerificationValuePrefix + record.VerificationToken,
+	}
+}
@@ -0,0 +1,343 @@
+package sso
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/config"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/ldap"
+	"cloudpan/internal/repository/models"
+)
+
+// LDAP默认参数
+const (
+	DefaultLDAPUserFilter     = "(|(uid={username})(mail={username}))"
+	DefaultLDAPEmailAttribute = "mail"
+	DefaultLDAPNameAttribute  = "displayName"
+	DefaultLDAPGroupAttribute = "memberOf"
+
+	// ldapUsernamePlaceholder 用户搜索过滤器中登录名的占位符
+	ldapUsernamePlaceholder = "{username}"
+)
+
+var (
+	// ErrLDAPUserNotFound 目录中没有该用户，调用方回退到本地账户认证
+	ErrLDAPUserNotFound = errors.New("ldap user not found")
+	// ErrLDAPUnavailable 目录服务器无法连接或服务账号绑定失败，调用方回退到本地账户认证
+	ErrLDAPUnavailable = errors.New("ldap directory unavailable")
+)
+
+// LDAPConn 目录连接，由 *ldap.Conn 实现
+type LDAPConn interface {
+	Bind(ctx context.Context, dn, password string) error
+	Search(ctx context.Context, req *ldap.SearchRequest) ([]*ldap.Entry, error)
+	Close() error
+}
+
+// LDAPDialer 建立目录连接
+type LDAPDialer func(ctx context.Context) (LDAPConn, error)
+
+// TeamMembership 团队成员增删，由 userrepo.SCIMRepository 实现
+type TeamMembership interface {
+	AddGroupMembers(ctx context.Context, teamID uint, userIDs []uint) error
+	RemoveGroupMembers(ctx context.Context, teamID uint, userIDs []uint) error
+}
+
+// LDAPOptions 目录认证选项
+type LDAPOptions struct {
+	Connection     ldap.Options      // 目录服务器连接参数
+	BindDN         string            // 搜索用户的服务账号DN，为空时匿名搜索
+	BindPassword   string            // 服务账号密码
+	BaseDN         string            // 用户搜索的基准DN
+	UserFilter     string            // 用户搜索过滤器，默认 DefaultLDAPUserFilter
+	EmailAttribute string            // 邮箱属性，默认 DefaultLDAPEmailAttribute
+	NameAttribute  string            // 显示名称属性，默认 DefaultLDAPNameAttribute
+	GroupAttribute string            // 所属组DN的属性，默认 DefaultLDAPGroupAttribute
+	RoleMappings   map[string]string // 组DN(小写)到角色的映射
+	DefaultRole    string            // 没有组匹配时的角色，默认user
+	TeamMappings   map[string]uint   // 组DN(小写)到团队ID的映射
+	JITEnabled     bool              // 首次登录时是否即时开通账户
+	Tenant         string            // 即时开通账户记录的租户标识
+	StorageQuota   int64             // 即时开通账户的存储配额
+}
+
+// LDAPOptionsFromConfig 从LDAP配置生成选项，组DN统一转为小写
+func LDAPOptionsFromConfig(cfg config.LDAPConfig) LDAPOptions {
+	options := LDAPOptions{
+		Connection: ldap.Options{
+			URL:                cfg.URL,
+			StartTLS:           cfg.StartTLS,
+			InsecureSkipVerify: cfg.InsecureSkipVerify,
+			Timeout:            cfg.Timeout,
+		},
+		BindDN:         cfg.BindDN,
+		BindPassword:   cfg.BindPassword,
+		BaseDN:         cfg.BaseDN,
+		UserFilter:     cfg.UserFilter,
+		EmailAttribute: cfg.EmailAttribute,
+		NameAttribute:  cfg.NameAttribute,
+		GroupAttribute: cfg.GroupAttribute,
+		DefaultRole:    cfg.DefaultRole,
+		JITEnabled:     cfg.JITEnabled,
+		Tenant:         cfg.Tenant,
+		StorageQuota:   cfg.StorageQuota,
+	}
+	if len(cfg.RoleMappings) > 0 {
+		options.RoleMappings = make(map[string]string, len(cfg.RoleMappings))
+		for group, role := range cfg.RoleMappings {
+			options.RoleMappings[strings.ToLower(group)] = role
+		}
+	}
+	if len(cfg.TeamMappings) > 0 {
+		options.TeamMappings = make(map[string]uint, len(cfg.TeamMappings))
+		for group, teamID := range cfg.TeamMappings {
+			options.TeamMappings[strings.ToLower(group)] = teamID
+		}
+	}
+	return options
+}
+
+// LDAPLoginResult 目录认证的结果
+type LDAPLoginResult struct {
+	User        *models.User // 登录的本地账户
+	Role        string       // 按组映射的角色
+	DN          string       // 目录中的用户DN
+	Provisioned bool         // 是否为本次登录即时开通的账户
+}
+
+// LDAPAuthenticator 企业目录(LDAP)认证接口
+//
+// 认证流程(先搜索后绑定)：
+// 1. 以服务账号绑定，按过滤器搜索登录名(uid或邮箱)，要求唯一匹配
+// 2. 以用户DN和登录密码绑定，验证密码
+// 3. 按目录中的邮箱找到本地账户，没有时即时开通；所属组映射为角色并同步映射的团队成员
+//
+// 目录中没有该用户时返回 ErrLDAPUserNotFound，目录不可用时返回 ErrLDAPUnavailable，
+// 调用方据此回退到本地账户认证；即时开通的账户密码随机，不能通过本地认证登录
+//
+// 使用示例：
+//
+//	authenticator := sso.NewLDAPAuthenticator(nil, userrepo.NewUserRepository(db), userrepo.NewSCIMRepository(db), sso.LDAPOptionsFromConfig(cfg.LDAP), logger)
+//	result, err := authenticator.Authenticate(ctx, "alice", password)
+type LDAPAuthenticator interface {
+	Authenticate(ctx context.Context, username, password string) (*LDAPLoginResult, error)
+}
+
+// ldapAuthenticator 目录认证实现
+type ldapAuthenticator struct {
+	dial    LDAPDialer
+	users   UserStore
+	teams   TeamMembership
+	options LDAPOptions
+	logger  *zap.Logger
+	now     func() time.Time
+}
+
+// NewLDAPAuthenticator 创建目录认证
+//
+// dial 可以为nil，此时按 options.Connection 连接目录服务器；teams 可以为nil，此时不同步团队成员
+func NewLDAPAuthenticator(dial LDAPDialer, users UserStore, teams TeamMembership, options LDAPOptions, logger *zap.Logger) LDAPAuthenticator {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	if dial == nil {
+		connection := options.Connection
+		dial = func(ctx context.Context) (LDAPConn, error) {
+			return ldap.Dial(ctx, connection)
+		}
+	}
+	if options.UserFilter == "" {
+		options.UserFilter = DefaultLDAPUserFilter
+	}
+	if options.EmailAttribute == "" {
+		options.EmailAttribute = DefaultLDAPEmailAttribute
+	}
+	if options.NameAttribute == "" {
+		options.NameAttribute = DefaultLDAPNameAttribute
+	}
+	if options.GroupAttribute == "" {
+		options.GroupAttribute = DefaultLDAPGroupAttribute
+	}
+	if !models.IsSSORole(options.DefaultRole) {
+		options.DefaultRole = models.SSORoles[0]
+	}
+	if options.StorageQuota <= 0 {
+		options.StorageQuota = DefaultStorageQuota
+	}
+	return &ldapAuthenticator{
+		dial:    dial,
+		users:   users,
+		teams:   teams,
+		options: options,
+		logger:  logger,
+		now:     time.Now,
+	}
+}
+
+// Authenticate 在目录中验证登录名和密码，返回关联或即时开通的本地账户
+func (a *ldapAuthenticator) Authenticate(ctx context.Context, username, password string) (*LDAPLoginResult, error) {
+	username = strings.TrimSpace(username)
+	if username == "" || password == "" {
+		return nil, pkgErrors.WrapError(ErrLoginFailed, "用户名或密码错误")
+	}
+
+	conn, err := a.dial(ctx)
+	if err != nil {
+		a.logger.Error("LDAP connection failed", zap.Error(err))
+		return nil, pkgErrors.WrapError(ErrLDAPUnavailable, "目录服务暂时不可用")
+	}
+	defer conn.Close()
+
+	entry, err := a.findUser(ctx, conn, username)
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.Bind(ctx, entry.DN, password); err != nil {
+		if ldap.IsResultCode(err, ldap.ResultInvalidCredentials) {
+			return nil, pkgErrors.WrapError(ErrLoginFailed, "用户名或密码错误")
+		}
+		a.logger.Error("LDAP user bind failed", zap.String("dn", entry.DN), zap.Error(err))
+		return nil, pkgErrors.WrapError(ErrLDAPUnavailable, "目录服务暂时不可用")
+	}
+
+	email := strings.ToLower(strings.TrimSpace(entry.Value(a.options.EmailAttribute)))
+	if emailDomain(email) == "" {
+		a.logger.Warn("LDAP user has no email", zap.String("dn", entry.DN))
+		return nil, pkgErrors.WrapError(ErrLoginFailed, "目录账户没有邮箱，无法登录")
+	}
+	groups := entry.Values(a.options.GroupAttribute)
+
+	account, provisioned, err := a.resolveUser(ctx, email, entry.Value(a.options.NameAttribute))
+	if err != nil {
+		return nil, err
+	}
+	a.syncTeams(ctx, account.ID, groups)
+
+	role := a.roleForGroups(groups)
+	a.logger.Info("LDAP login completed",
+		zap.Uint("user_id", account.ID),
+		zap.String("dn", entry.DN),
+		zap.String("role", role),
+		zap.Bool("provisioned", provisioned))
+	return &LDAPLoginResult{User: account, Role: role, DN: entry.DN, Provisioned: provisioned}, nil
+}
+
+// findUser 以服务账号绑定并按登录名搜索唯一的用户条目
+func (a *ldapAuthenticator) findUser(ctx context.Context, conn LDAPConn, username string) (*ldap.Entry, error) {
+	if a.options.BindDN != "" {
+		if err := conn.Bind(ctx, a.options.BindDN, a.options.BindPassword); err != nil {
+			a.logger.Error("LDAP service account bind failed", zap.String("bind_dn", a.options.BindDN), zap.Error(err))
+			return nil, pkgErrors.WrapError(ErrLDAPUnavailable, "目录服务暂时不可用")
+		}
+	}
+
+	// 登录名转义后再拼入过滤器，防止注入通配符或额外条件
+	filter := strings.ReplaceAll(a.options.UserFilter, ldapUsernamePlaceholder, ldap.EscapeFilter(username))
+	entries, err := conn.Search(ctx, &ldap.SearchRequest{
+		BaseDN:     a.options.BaseDN,
+		Scope:      ldap.ScopeWholeSubtree,
+		Filter:     filter,
+		Attributes: []string{a.options.EmailAttribute, a.options.NameAttribute, a.options.GroupAttribute},
+		SizeLimit:  2,
+	})
+	switch {
+	case ldap.IsResultCode(err, ldap.ResultSizeLimitExceeded) || (err == nil && len(entries) > 1):
+		a.logger.Warn("LDAP login matched multiple entries", zap.String("username", username))
+		return nil, pkgErrors.WrapError(ErrLoginFailed, "目录中有多个匹配的账户，请联系管理员")
+	case err != nil:
+		a.logger.Error("LDAP user search failed", zap.String("base_dn", a.options.BaseDN), zap.Error(err))
+		return nil, pkgErrors.WrapError(ErrLDAPUnavailable, "目录服务暂时不可用")
+	case len(entries) == 0:
+		return nil, ErrLDAPUserNotFound
+	}
+	return entries[0], nil
+}
+
+// resolveUser 按邮箱找到本地账户，没有时即时开通
+//
+// 目录由部署方管理，邮箱与已有账户相同时直接关联，不要求额外验证
+func (a *ldapAuthenticator) resolveUser(ctx context.Context, email, name string) (*models.User, bool, error) {
+	account, err := a.users.GetByEmail(ctx, email)
+	if err == nil {
+		return account, false, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, pkgErrors.WrapError(err, "获取用户失败")
+	}
+	if !a.options.JITEnabled {
+		return nil, false, pkgErrors.WrapError(ErrLoginFailed, "账户不存在，请联系管理员开通")
+	}
+
+	account, err = provisionAccount(ctx, a.users, a.options.Tenant, email, name, a.options.StorageQuota, a.now())
+	if err != nil {
+		return nil, false, err
+	}
+	a.logger.Info("LDAP user provisioned",
+		zap.Uint("user_id", account.ID),
+		zap.String("username", account.Username))
+	return account, true, nil
+}
+
+// roleForGroups 按组映射角色，多个组匹配时取权限最高的角色
+func (a *ldapAuthenticator) roleForGroups(groups []string) string {
+	role := a.options.DefaultRole
+	for _, group := range groups {
+		mapped := a.options.RoleMappings[strings.ToLower(group)]
+		if slices.Index(models.SSORoles, mapped) > slices.Index(models.SSORoles, role) {
+			role = mapped
+		}
+	}
+	return role
+}
+
+// syncTeams 按组同步映射的团队：属于组时加入团队，不再属于时移出，失败只记录日志不影响登录
+func (a *ldapAuthenticator) syncTeams(ctx context.Context, userID uint, groups []string) {
+	if a.teams == nil || len(a.options.TeamMappings) == 0 {
+		return
+	}
+	member := make(map[string]bool, len(groups))
+	for _, group := range groups {
+		member[strings.ToLower(group)] = true
+	}
+	for group, teamID := range a.options.TeamMappings {
+		var err error
+		if member[group] {
+			err = a.teams.AddGroupMembers(ctx, teamID, []uint{userID})
+		} else {
+			err = a.teams.RemoveGroupMembers(ctx, teamID, []uint{userID})
+		}
+		if err != nil {
+			a.logger.Warn("Failed to sync LDAP team membership",
+				zap.Uint("user_id", userID),
+				zap.Uint("team_id", teamID),
+				zap.Error(err))
+		}
+	}
+}
+
+var (
+	defaultLDAPMu sync.RWMutex
+	defaultLDAP   LDAPAuthenticator
+)
+
+// SetDefaultLDAP 设置全局目录认证，启动时调用
+func SetDefaultLDAP(authenticator LDAPAuthenticator) {
+	defaultLDAPMu.Lock()
+	defer defaultLDAPMu.Unlock()
+	defaultLDAP = authenticator
+}
+
+// DefaultLDAP 返回全局目录认证，未启用LDAP时返回nil
+func DefaultLDAP() LDAPAuthenticator {
+	defaultLDAPMu.RLock()
+	defer defaultLDAPMu.RUnlock()
+	return defaultLDAP
+}
@@ -0,0 +1,179 @@
+package sso
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"cloudpan/internal/pkg/config"
+	"cloudpan/internal/pkg/ldap"
+	"cloudpan/internal/repository/models"
+)
+
+// fakeDirectory 内存目录，按DN校验密码，搜索时按登录名匹配uid或mail
+type fakeDirectory struct {
+	passwords map[string]string
+	entries   []*ldap.Entry
+	down      bool
+
+	filters []string
+	closed  int
+}
+
+func (d *fakeDirectory) dial(context.Context) (LDAPConn, error) {
+	if d.down {
+		return nil, errors.New("connection refused")
+	}
+	return d, nil
+}
+
+func (d *fakeDirectory) Bind(_ context.Context, dn, password string) error {
+	if expected, ok := d.passwords[dn]; ok && expected == password {
+		return nil
+	}
+	return &ldap.Error{Code: ldap.ResultInvalidCredentials}
+}
+
+func (d *fakeDirectory) Search(_ context.Context, req *ldap.SearchRequest) ([]*ldap.Entry, error) {
+	d.filters = append(d.filters, req.Filter)
+	var found []*ldap.Entry
+	filter := strings.ToLower(req.Filter)
+	for _, entry := range d.entries {
+		if strings.Contains(filter, "(uid="+strings.ToLower(entry.Value("uid"))+")") ||
+			strings.Contains(filter, "(mail="+strings.ToLower(entry.Value("mail"))+")") {
+			found = append(found, entry)
+		}
+	}
+	return found, nil
+}
+
+func (d *fakeDirectory) Close() error {
+	d.closed++
+	return nil
+}
+
+// memoryTeams 记录团队成员
+type memoryTeams map[uint][]uint
+
+func (m memoryTeams) AddGroupMembers(_ context.Context, teamID uint, userIDs []uint) error {
+	for _, id := range userIDs {
+		if !slices.Contains(m[teamID], id) {
+			m[teamID] = append(m[teamID], id)
+		}
+	}
+	return nil
+}
+
+func (m memoryTeams) RemoveGroupMembers(_ context.Context, teamID uint, userIDs []uint) error {
+	m[teamID] = slices.DeleteFunc(m[teamID], func(id uint) bool { return slices.Contains(userIDs, id) })
+	return nil
+}
+
+const (
+	ldapServiceDN = "cn=cloudpan,ou=services,dc=example,dc=com"
+	ldapAliceDN   = "uid=alice,ou=people,dc=example,dc=com"
+)
+
+func newLDAPFixture(options LDAPOptions) (*fakeDirectory, *memoryUsers, memoryTeams, LDAPAuthenticator) {
+	directory := &fakeDirectory{
+		passwords: map[string]string{ldapServiceDN: "service-secret", ldapAliceDN: "alice-secret"},
+		entries: []*ldap.Entry{{
+			DN: ldapAliceDN,
+			Attributes: map[string][]string{
+				"uid":         {"alice"},
+				"mail":        {"Alice@Example.com"},
+				"displayName": {"Alice Liddell"},
+				"memberOf":    {"CN=Admins,OU=Groups,DC=example,DC=com", "cn=dev,ou=groups,dc=example,dc=com"},
+			},
+		}},
+	}
+	users := &memoryUsers{users: make(map[uint]*models.User)}
+	teams := memoryTeams{}
+	options.BindDN, options.BindPassword, options.BaseDN = ldapServiceDN, "service-secret", "dc=example,dc=com"
+	return directory, users, teams, NewLDAPAuthenticator(directory.dial, users, teams, options, nil)
+}
+
+func TestLDAPAuthenticate(t *testing.T) {
+	ctx := context.Background()
+	directory, users, teams, authenticator := newLDAPFixture(mappedLDAPOptions())
+	teams[9] = []uint{1}
+
+	result, err := authenticator.Authenticate(ctx, "alice", "alice-secret")
+	require.NoError(t, err)
+	assert.True(t, result.Provisioned)
+	assert.Equal(t, ldapAliceDN, result.DN)
+	assert.Equal(t, "admin", result.Role, "组DN不区分大小写，取权限最高的角色")
+	assert.Equal(t, "alice@example.com", result.User.Email)
+	assert.Equal(t, "Alice Liddell", *result.User.DisplayName)
+	assert.Equal(t, "tenant-a", (*result.User.Profile)[profileKeyTenant])
+	assert.Equal(t, []uint{result.User.ID}, teams[3], "加入组映射的团队")
+	assert.Empty(t, teams[9], "不在组中时移出映射的团队")
+	assert.Equal(t, 1, directory.closed)
+
+	// 再次登录关联已开通的账户
+	result, err = authenticator.Authenticate(ctx, "alice@example.com", "alice-secret")
+	require.NoError(t, err)
+	assert.False(t, result.Provisioned)
+	assert.Len(t, users.users, 1)
+
+	_, err = authenticator.Authenticate(ctx, "alice", "wrong")
+	assert.ErrorIs(t, err, ErrLoginFailed)
+}
+
+func TestLDAPAuthenticate_Fallback(t *testing.T) {
+	ctx := context.Background()
+	directory, _, _, authenticator := newLDAPFixture(LDAPOptions{JITEnabled: true})
+
+	_, err := authenticator.Authenticate(ctx, "bob", "secret")
+	assert.ErrorIs(t, err, ErrLDAPUserNotFound)
+
+	// 登录名中的特殊字符被转义，不能改写过滤器
+	_, err = authenticator.Authenticate(ctx, "*)(uid=alice", "alice-secret")
+	assert.ErrorIs(t, err, ErrLDAPUserNotFound)
+	assert.Equal(t, `(|(uid=\2a\29\28uid=alice)(mail=\2a\29\28uid=alice))`, directory.filters[len(directory.filters)-1])
+
+	directory.passwords[ldapServiceDN] = "rotated"
+	_, err = authenticator.Authenticate(ctx, "alice", "alice-secret")
+	assert.ErrorIs(t, err, ErrLDAPUnavailable, "服务账号绑定失败时按目录不可用处理")
+
+	directory.down = true
+	_, err = authenticator.Authenticate(ctx, "alice", "alice-secret")
+	assert.ErrorIs(t, err, ErrLDAPUnavailable)
+}
+
+func TestLDAPAuthenticate_ExistingAccount(t *testing.T) {
+	ctx := context.Background()
+	_, users, _, authenticator := newLDAPFixture(LDAPOptions{})
+
+	_, err := authenticator.Authenticate(ctx, "alice", "alice-secret")
+	require.ErrorIs(t, err, ErrLoginFailed, "未开启即时开通时不创建账户")
+	assert.Contains(t, err.Error(), "联系管理员")
+
+	existing := &models.User{Email: "alice@example.com", Username: "alice"}
+	require.NoError(t, users.Create(ctx, existing))
+	result, err := authenticator.Authenticate(ctx, "alice", "alice-secret")
+	require.NoError(t, err)
+	assert.Equal(t, existing.ID, result.User.ID)
+	assert.Equal(t, "user", result.Role, "没有配置映射时使用默认角色")
+}
+
+// mappedLDAPOptions 带角色、团队映射和即时开通的选项，映射的组DN大小写与目录不同
+func mappedLDAPOptions() LDAPOptions {
+	return LDAPOptionsFromConfig(config.LDAPConfig{
+		RoleMappings: map[string]string{
+			"cn=Admins,ou=groups,dc=example,dc=com": "admin",
+			"cn=dev,ou=groups,dc=example,dc=com":    "moderator",
+		},
+		TeamMappings: map[string]uint{
+			"cn=dev,ou=groups,dc=example,dc=com": 3,
+			"cn=ops,ou=groups,dc=example,dc=com": 9,
+		},
+		JITEnabled: true,
+		Tenant:     "tenant-a",
+	})
+}
@@ -0,0 +1,442 @@
+package sso
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/cache"
+	basemodels "cloudpan/internal/pkg/database/models"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/oidc"
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+)
+
+// 即时开通账户参数
+const (
+	profileKeyTenant     = "tenant" // 与 featureflag.ProfileKeyTenant 一致，按租户评估特性开关
+	maxUsernameLength    = 40       // 由邮箱生成的用户名最大长度
+	usernameAttempts     = 5        // 用户名冲突时追加随机后缀的重试次数
+	usernameSuffixLength = 4        // 随机后缀长度(十六进制字符)
+	randomPasswordLength = 32       // 开通账户的随机密码长度，用户只能通过SSO登录或重置密码
+)
+
+// Discover 按邮箱域名查询单点登录配置，域名未验证或身份提供方未启用时返回 SSO=false
+func (s *service) Discover(ctx context.Context, email string) (*Discovery, error) {
+	provider, err := s.providerForEmail(ctx, email)
+	if err != nil || provider == nil {
+		return &Discovery{}, err
+	}
+	return &Discovery{
+		SSO:          true,
+		Tenant:       provider.Tenant,
+		ProviderName: provider.Name,
+		Enforced:     provider.EnforceSSO,
+	}, nil
+}
+
+// CheckPasswordLogin 检查邮箱是否允许密码登录，域名开启强制SSO时返回 ErrSSORequired 和单点登录配置
+func (s *service) CheckPasswordLogin(ctx context.Context, email string) (*Discovery, error) {
+	discovery, err := s.Discover(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	if discovery.Enforced {
+		return discovery, pkgErrors.WrapError(ErrSSORequired, "该邮箱域名已启用企业单点登录，请使用SSO登录")
+	}
+	return discovery, nil
+}
+
+// Authorize 生成身份提供方的授权地址，并保存校验回调需要的state、nonce和PKCE校验码
+func (s *service) Authorize(ctx context.Context, req *AuthorizeRequest) (*Authorization, error) {
+	if s.options.RedirectURL == "" {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrConfigInvalid, "未配置单点登录回调地址")
+	}
+
+	var provider *models.SSOProvider
+	switch {
+	case strings.TrimSpace(req.Tenant) != "":
+		found, err := s.repo.GetProviderByTenant(ctx, strings.ToLower(strings.TrimSpace(req.Tenant)))
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.WrapError(err, "查询身份提供方失败")
+		}
+		if err == nil && found.IsActive {
+			provider = found
+		}
+	case strings.TrimSpace(req.Email) != "":
+		found, err := s.providerForEmail(ctx, req.Email)
+		if err != nil {
+			return nil, err
+		}
+		provider = found
+	default:
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "邮箱和租户不能同时为空")
+	}
+	if provider == nil {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "未找到可用的单点登录配置")
+	}
+
+	client, err := s.oidcProvider(ctx, provider)
+	if err != nil {
+		s.logger.Error("SSO provider discovery failed",
+			zap.Uint("provider_id", provider.ID),
+			zap.String("issuer", provider.Issuer),
+			zap.Error(err))
+		return nil, pkgErrors.WrapError(pkgErrors.ErrNetworkTimeout, "身份提供方暂时不可用")
+	}
+
+	state, err := oidc.NewNonce()
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := oidc.NewNonce()
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := oidc.NewVerifier()
+	if err != nil {
+		return nil, err
+	}
+	pending := &cache.SSOState{
+		State:      state,
+		ProviderID: provider.ID,
+		Nonce:      nonce,
+		Verifier:   verifier,
+		RememberMe: req.RememberMe,
+		ExpiresAt:  s.now().Add(s.options.StateTTL),
+	}
+	if err := s.states.Save(ctx, pending); err != nil {
+		return nil, pkgErrors.WrapError(err, "保存单点登录请求失败")
+	}
+
+	return &Authorization{
+		AuthorizationURL: client.AuthCodeURL(s.clientConfig(provider), state, nonce, verifier),
+		State:            state,
+		ExpiresAt:        pending.ExpiresAt,
+	}, nil
+}
+
+// Complete 用授权码换取并验证ID令牌，返回关联或即时开通的本地账户
+//
+// 已关联的身份按subject找到账户；首次登录要求邮箱已由身份提供方验证、
+// 且邮箱域名已由该身份提供方认领并通过DNS验证，同邮箱的已有账户直接关联
+func (s *service) Complete(ctx context.Context, state, code string) (*LoginResult, error) {
+	if state == "" || code == "" {
+		return nil, pkgErrors.WrapError(ErrLoginFailed, "缺少state或授权码")
+	}
+	pending, err := s.states.Take(ctx, state)
+	if err != nil {
+		if errors.Is(err, cache.ErrSSOStateNotFound) {
+			return nil, pkgErrors.WrapError(ErrLoginFailed, "登录请求已失效，请重新登录")
+		}
+		return nil, pkgErrors.WrapError(err, "读取单点登录请求失败")
+	}
+
+	provider, err := s.repo.GetProvider(ctx, pending.ProviderID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.WrapError(ErrLoginFailed, "身份提供方已删除")
+		}
+		return nil, pkgErrors.WrapError(err, "获取身份提供方失败")
+	}
+	if !provider.IsActive {
+		return nil, pkgErrors.WrapError(ErrLoginFailed, "身份提供方已停用")
+	}
+
+	client, err := s.oidcProvider(ctx, provider)
+	if err != nil {
+		s.logger.Error("SSO provider discovery failed",
+			zap.Uint("provider_id", provider.ID),
+			zap.Error(err))
+		return nil, pkgErrors.WrapError(pkgErrors.ErrNetworkTimeout, "身份提供方暂时不可用")
+	}
+	cfg := s.clientConfig(provider)
+	token, err := client.Exchange(ctx, cfg, code, pending.Verifier)
+	if err != nil {
+		s.logger.Warn("SSO code exchange failed",
+			zap.Uint("provider_id", provider.ID),
+			zap.Error(err))
+		return nil, pkgErrors.WrapError(ErrLoginFailed, "身份提供方拒绝了登录请求")
+	}
+	idToken, err := client.Verify(ctx, cfg, token.IDToken, pending.Nonce)
+	if err != nil {
+		s.logger.Warn("SSO id token rejected",
+			zap.Uint("provider_id", provider.ID),
+			zap.Error(err))
+		return nil, pkgErrors.WrapError(ErrLoginFailed, "身份令牌验证失败")
+	}
+
+	role := provider.RoleForGroups(idToken.Strings(provider.GroupsClaim))
+	account, provisioned, err := s.resolveUser(ctx, provider, idToken)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	identity := &models.SSOIdentity{
+		ProviderID:  provider.ID,
+		Subject:     idToken.Subject,
+		UserID:      account.ID,
+		Email:       strings.ToLower(idToken.Email),
+		Role:        role,
+		LastLoginAt: &now,
+	}
+	if err := s.repo.SaveIdentity(ctx, identity); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("SSO login completed",
+		zap.Uint("provider_id", provider.ID),
+		zap.String("tenant", provider.Tenant),
+		zap.Uint("user_id", account.ID),
+		zap.String("role", role),
+		zap.Bool("provisioned", provisioned))
+	return &LoginResult{
+		User:        account,
+		Role:        role,
+		RememberMe:  pending.RememberMe,
+		Provisioned: provisioned,
+		Tenant:      provider.Tenant,
+	}, nil
+}
+
+// resolveUser 按身份关联或邮箱找到本地账户，都没有时即时开通
+func (s *service) resolveUser(ctx context.Context, provider *models.SSOProvider, idToken *oidc.IDToken) (*models.User, bool, error) {
+	identity, err := s.repo.GetIdentity(ctx, provider.ID, idToken.Subject)
+	switch {
+	case err == nil:
+		account, err := s.users.GetByID(ctx, identity.UserID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, false, pkgErrors.WrapError(ErrLoginFailed, "关联的账户已不存在")
+			}
+			return nil, false, pkgErrors.WrapError(err, "获取用户失败")
+		}
+		return account, false, nil
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, false, pkgErrors.WrapError(err, "查询单点登录身份失败")
+	}
+
+	// 首次登录：邮箱必须已验证且属于该身份提供方认领的域名，防止其他租户冒用邮箱
+	email := strings.ToLower(strings.TrimSpace(idToken.Email))
+	if email == "" || !idToken.EmailVerified {
+		return nil, false, pkgErrors.WrapError(ErrLoginFailed, "身份提供方未提供已验证的邮箱")
+	}
+	domain, err := s.repo.GetDomain(ctx, emailDomain(email))
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, pkgErrors.WrapError(err, "查询域名失败")
+	}
+	if err != nil || domain.ProviderID != provider.ID || !domain.IsVerified() {
+		s.logger.Warn("SSO login rejected for unclaimed email domain",
+			zap.Uint("provider_id", provider.ID),
+			zap.String("email", email))
+		return nil, false, pkgErrors.WrapError(ErrLoginFailed, "邮箱域名未由该身份提供方认证")
+	}
+
+	account, err := s.users.GetByEmail(ctx, email)
+	if err == nil {
+		return account, false, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, pkgErrors.WrapError(err, "获取用户失败")
+	}
+	if !provider.JITEnabled {
+		return nil, false, pkgErrors.WrapError(ErrLoginFailed, "账户不存在，请联系管理员开通")
+	}
+
+	account, err = s.provisionUser(ctx, provider, email, idToken.Name)
+	if err != nil {
+		return nil, false, err
+	}
+	return account, true, nil
+}
+
+// provisionUser 即时开通账户并记录日志
+func (s *service) provisionUser(ctx context.Context, provider *models.SSOProvider, email, name string) (*models.User, error) {
+	account, err := provisionAccount(ctx, s.users, provider.Tenant, email, name, s.options.StorageQuota, s.now())
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info("SSO user provisioned",
+		zap.Uint("provider_id", provider.ID),
+		zap.String("tenant", provider.Tenant),
+		zap.Uint("user_id", account.ID),
+		zap.String("username", account.Username))
+	return account, nil
+}
+
+// provisionAccount 开通账户：用户名由邮箱生成，密码随机，邮箱视为已验证，单点登录和目录同步共用
+func provisionAccount(ctx context.Context, users UserStore, tenant, email, name string, storageQuota int64, now time.Time) (*models.User, error) {
+	username, err := availableUsername(ctx, users, email)
+	if err != nil {
+		return nil, err
+	}
+	password, err := utils.GenerateHex(randomPasswordLength)
+	if err != nil {
+		return nil, pkgErrors.WrapError(err, "生成随机密码失败")
+	}
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		return nil, pkgErrors.WrapError(err, "密码加密失败")
+	}
+
+	profile := basemodels.JSONMap{profileKeyTenant: tenant}
+	account := &models.User{
+		Email:           email,
+		Username:        username,
+		PasswordHash:    hash,
+		Status:          "active",
+		StorageQuota:    storageQuota,
+		EmailVerified:   true,
+		EmailVerifiedAt: &now,
+		Profile:         &profile,
+	}
+	if name = strings.TrimSpace(name); name != "" {
+		account.DisplayName = &name
+	}
+	if err := users.Create(ctx, account); err != nil {
+		return nil, pkgErrors.WrapError(err, "开通账户失败")
+	}
+	return account, nil
+}
+
+// availableUsername 由邮箱前缀生成用户名，冲突或为保留名称时追加随机后缀
+func availableUsername(ctx context.Context, users UserStore, email string) (string, error) {
+	base := usernameFromEmail(email)
+	candidate := base
+	for i := 0; i < usernameAttempts; i++ {
+		if !slices.Contains(utils.ReservedUsernames(), candidate) {
+			exists, err := users.ExistsByUsername(ctx, candidate)
+			if err != nil {
+				return "", pkgErrors.WrapError(err, "检查用户名失败")
+			}
+			if !exists {
+				return candidate, nil
+			}
+		}
+		suffix, err := utils.GenerateHex(usernameSuffixLength)
+		if err != nil {
+			return "", pkgErrors.WrapError(err, "生成用户名失败")
+		}
+		candidate = base + "-" + suffix
+	}
+	return "", pkgErrors.WrapError(pkgErrors.ErrResourceExists, "无法生成可用的用户名")
+}
+
+// usernameFromEmail 取邮箱前缀中的字母和数字，其他字符替换为连字符
+func usernameFromEmail(email string) string {
+	local, _, _ := strings.Cut(email, "@")
+	var b strings.Builder
+	lastDash := true
+	for _, r := range strings.ToLower(local) {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			b.WriteRune(r)
+			lastDash = false
+		} else if !lastDash {
+			b.WriteByte('-')
+			lastDash = true
+		}
+	}
+	username := strings.Trim(b.String(), "-")
+	if len(username) > maxUsernameLength {
+		username = strings.TrimRight(username[:maxUsernameLength], "-")
+	}
+	// 用户名不能以数字开头
+	if username == "" || unicode.IsDigit(rune(username[0])) {
+		username = "u" + username
+	}
+	return username
+}
+
+// providerForEmail 返回邮箱域名已验证且启用的身份提供方，没有时返回nil
+func (s *service) providerForEmail(ctx context.Context, email string) (*models.SSOProvider, error) {
+	domainName := emailDomain(strings.ToLower(strings.TrimSpace(email)))
+	if domainName == "" {
+		return nil, nil
+	}
+	domain, err := s.repo.GetDomain(ctx, domainName)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, pkgErrors.WrapError(err, "查询域名失败")
+	}
+	if !domain.IsVerified() {
+		return nil, nil
+	}
+	provider, err := s.repo.GetProvider(ctx, domain.ProviderID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, pkgErrors.WrapError(err, "获取身份提供方失败")
+	}
+	if !provider.IsActive {
+		return nil, nil
+	}
+	return provider, nil
+}
+
+// oidcProvider 返回缓存的身份提供方发现结果，签发者变更或缓存过期时重新获取
+func (s *service) oidcProvider(ctx context.Context, provider *models.SSOProvider) (*oidc.Provider, error) {
+	s.mu.Lock()
+	cached, ok := s.providers[provider.ID]
+	s.mu.Unlock()
+	if ok && cached.issuer == provider.Issuer && s.now().Sub(cached.fetchedAt) < providerCacheTTL {
+		return cached.provider, nil
+	}
+
+	discovered, err := s.discover(ctx, provider.Issuer)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.providers[provider.ID] = &cachedProvider{issuer: provider.Issuer, provider: discovered, fetchedAt: s.now()}
+	s.mu.Unlock()
+	return discovered, nil
+}
+
+// discover 获取签发者的发现文档
+func (s *service) discover(ctx context.Context, issuer string) (*oidc.Provider, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.options.HTTPTimeout)
+	defer cancel()
+	provider, err := oidc.NewProvider(ctx, s.client, issuer)
+	if err != nil {
+		return nil, fmt.Errorf("discover %s: %w", issuer, err)
+	}
+	return provider, nil
+}
+
+// forgetProvider 清除身份提供方的发现缓存
+func (s *service) forgetProvider(id uint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.providers, id)
+}
+
+// clientConfig 生成身份提供方的客户端参数
+func (s *service) clientConfig(provider *models.SSOProvider) oidc.Config {
+	return oidc.Config{
+		ClientID:     provider.ClientID,
+		ClientSecret: provider.ClientSecret,
+		RedirectURL:  s.options.RedirectURL,
+		Scopes:       provider.ScopeList(),
+	}
+}
+
+// emailDomain 返回邮箱的域名部分，格式不正确时返回空
+func emailDomain(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at <= 0 || at == len(email)-1 {
+		return ""
+	}
+	return email[at+1:]
+}
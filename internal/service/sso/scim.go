@@ -0,0 +1,435 @@
+package sso
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/cache"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+	userrepo "cloudpan/internal/repository/user"
+)
+
+// SCIM 2.0 消息和资源的schema
+const (
+	SCIMSchemaUser                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SCIMSchemaGroup                 = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SCIMSchemaListResponse          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SCIMSchemaPatchOp               = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SCIMSchemaError                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+	SCIMSchemaServiceProviderConfig = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+)
+
+// 目录同步默认参数
+const (
+	SCIMTokenPrefix            = "scim_" // 令牌前缀，便于在密钥扫描中识别
+	DefaultSCIMPageSize        = 100     // 列表接口每页最大数量
+	DefaultSCIMMaxGroupMembers = 500     // 同步创建的团队最大成员数
+
+	scimTokenLength        = 48              // 令牌随机部分长度(十六进制字符)
+	scimTokenDisplayLength = 12              // 记录的令牌前缀长度
+	scimTouchInterval      = 5 * time.Minute // 令牌使用时间的最小记录间隔，避免每个请求都写库
+)
+
+var (
+	// ErrSCIMUnauthorized 目录同步令牌无效、已吊销或身份提供方未启用
+	ErrSCIMUnauthorized = errors.New("scim unauthorized")
+	// ErrSCIMInvalidFilter 不支持的过滤表达式
+	ErrSCIMInvalidFilter = errors.New("scim invalid filter")
+	// ErrSCIMInvalidPath PATCH操作的路径无效
+	ErrSCIMInvalidPath = errors.New("scim invalid path")
+)
+
+// SCIMUserStore 目录同步需要的用户数据访问，由 userrepo.UserRepository 实现
+type SCIMUserStore interface {
+	UserStore
+	Update(ctx context.Context, user *models.User) error
+}
+
+// SCIMOptions 目录同步选项
+type SCIMOptions struct {
+	StorageQuota    int64 // 开通账户的存储配额
+	MaxGroupMembers int   // 同步创建的团队最大成员数
+	PageSize        int   // 列表接口每页最大数量
+}
+
+// SCIMClient 通过令牌认证的身份提供方
+type SCIMClient struct {
+	ProviderID uint   // 身份提供方ID
+	Tenant     string // 租户标识
+	OwnerID    uint   // 签发令牌的管理员，同步创建的团队归其所有
+}
+
+// SCIMTokenInfo 目录同步令牌信息，明文令牌只在签发时返回一次
+type SCIMTokenInfo struct {
+	Token       string     `json:"token,omitempty" example:"scim_3f9a..."` // 明文令牌，只在签发时返回
+	TokenPrefix string     `json:"token_prefix" example:"scim_3f9a1c"`     // 令牌前缀，用于识别
+	CreatedBy   uint       `json:"created_by"`                             // 签发令牌的管理员ID
+	CreatedAt   time.Time  `json:"created_at"`                             // 签发时间
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`                 // 最近使用时间
+}
+
+// SCIMMeta 资源元数据
+type SCIMMeta struct {
+	ResourceType string     `json:"resourceType"`
+	Created      *time.Time `json:"created,omitempty"`
+	LastModified *time.Time `json:"lastModified,omitempty"`
+}
+
+// SCIMName 用户姓名
+type SCIMName struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+// SCIMEmail 用户邮箱
+type SCIMEmail struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+// SCIMUser SCIM用户资源，id为本地账户UUID
+type SCIMUser struct {
+	Schemas     []string    `json:"schemas"`
+	ID          string      `json:"id,omitempty"`
+	ExternalID  string      `json:"externalId,omitempty"`
+	UserName    string      `json:"userName"`
+	Name        *SCIMName   `json:"name,omitempty"`
+	DisplayName string      `json:"displayName,omitempty"`
+	Emails      []SCIMEmail `json:"emails,omitempty"`
+	Active      *bool       `json:"active,omitempty"`
+	Meta        *SCIMMeta   `json:"meta,omitempty"`
+}
+
+// SCIMGroupMember 组成员，value为成员账户UUID
+type SCIMGroupMember struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+}
+
+// SCIMGroup SCIM组资源，id为对应团队的UUID
+type SCIMGroup struct {
+	Schemas     []string          `json:"schemas"`
+	ID          string            `json:"id,omitempty"`
+	ExternalID  string            `json:"externalId,omitempty"`
+	DisplayName string            `json:"displayName"`
+	Members     []SCIMGroupMember `json:"members,omitempty"`
+	Meta        *SCIMMeta         `json:"meta,omitempty"`
+}
+
+// SCIMListQuery 列表查询参数
+type SCIMListQuery struct {
+	Filter         string // 过滤表达式，只支持 attr eq "value"
+	StartIndex     int    // 起始序号，从1开始
+	Count          int    // 每页数量，小于0时使用默认值
+	ExcludeMembers bool   // 组列表不返回成员(excludedAttributes=members)
+}
+
+// SCIMListResponse 列表响应
+type SCIMListResponse struct {
+	Schemas      []string    `json:"schemas"`
+	TotalResults int64       `json:"totalResults"`
+	StartIndex   int         `json:"startIndex"`
+	ItemsPerPage int         `json:"itemsPerPage"`
+	Resources    interface{} `json:"Resources"`
+}
+
+// SCIMPatchOperation PATCH操作，op不区分大小写
+type SCIMPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// SCIMPatchRequest PATCH请求
+type SCIMPatchRequest struct {
+	Schemas    []string             `json:"schemas"`
+	Operations []SCIMPatchOperation `json:"Operations"`
+}
+
+// SCIMService 企业目录同步(SCIM 2.0)服务接口
+//
+// 管理员为身份提供方签发令牌后，Okta、Azure AD等目录使用该令牌调用SCIM接口：
+// 1. 用户：开通账户或按邮箱关联已有账户，active=false时停用账户并吊销登录令牌，删除时停用并移出同步的团队
+// 2. 组：每个组对应一个团队，组成员同步为团队成员，成员必须是同一身份提供方同步的用户
+//
+// 用户邮箱必须属于该身份提供方已验证的域名；userName、externalId和组名称在身份提供方内唯一，
+// 冲突时返回 ErrResourceExists
+//
+// 使用示例：
+//
+//	service := sso.NewSCIMService(userrepo.NewSSORepository(db), userrepo.NewSCIMRepository(db), userrepo.NewUserRepository(db), cache.DefaultTokenStore(), sso.SCIMOptions{}, logger)
+//	client, err := service.Authenticate(ctx, bearerToken)
+//	user, err := service.CreateUser(ctx, client, &sso.SCIMUser{UserName: "alice@acme.com"})
+type SCIMService interface {
+	// 令牌管理
+	IssueToken(ctx context.Context, providerID, adminID uint) (*SCIMTokenInfo, error)
+	GetToken(ctx context.Context, providerID uint) (*SCIMTokenInfo, error)
+	RevokeToken(ctx context.Context, providerID uint) error
+	Authenticate(ctx context.Context, token string) (*SCIMClient, error)
+
+	// 用户
+	ListUsers(ctx context.Context, client *SCIMClient, query *SCIMListQuery) (*SCIMListResponse, error)
+	GetUser(ctx context.Context, client *SCIMClient, id string) (*SCIMUser, error)
+	CreateUser(ctx context.Context, client *SCIMClient, req *SCIMUser) (*SCIMUser, error)
+	ReplaceUser(ctx context.Context, client *SCIMClient, id string, req *SCIMUser) (*SCIMUser, error)
+	PatchUser(ctx context.Context, client *SCIMClient, id string, req *SCIMPatchRequest) (*SCIMUser, error)
+	DeleteUser(ctx context.Context, client *SCIMClient, id string) error
+
+	// 组
+	ListGroups(ctx context.Context, client *SCIMClient, query *SCIMListQuery) (*SCIMListResponse, error)
+	GetGroup(ctx context.Context, client *SCIMClient, id string) (*SCIMGroup, error)
+	CreateGroup(ctx context.Context, client *SCIMClient, req *SCIMGroup) (*SCIMGroup, error)
+	ReplaceGroup(ctx context.Context, client *SCIMClient, id string, req *SCIMGroup) (*SCIMGroup, error)
+	PatchGroup(ctx context.Context, client *SCIMClient, id string, req *SCIMPatchRequest) (*SCIMGroup, error)
+	DeleteGroup(ctx context.Context, client *SCIMClient, id string) error
+}
+
+// scimService 企业目录同步服务实现
+type scimService struct {
+	repo    userrepo.SSORepository
+	scim    userrepo.SCIMRepository
+	users   SCIMUserStore
+	tokens  cache.TokenStore
+	options SCIMOptions
+	logger  *zap.Logger
+	now     func() time.Time
+}
+
+// NewSCIMService 创建企业目录同步服务
+//
+// tokens 可以为nil，此时停用账户不吊销已签发的登录令牌
+func NewSCIMService(repo userrepo.SSORepository, scim userrepo.SCIMRepository, users SCIMUserStore, tokens cache.TokenStore, options SCIMOptions, logger *zap.Logger) SCIMService {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	if options.StorageQuota <= 0 {
+		options.StorageQuota = DefaultStorageQuota
+	}
+	if options.MaxGroupMembers <= 0 {
+		options.MaxGroupMembers = DefaultSCIMMaxGroupMembers
+	}
+	if options.PageSize <= 0 {
+		options.PageSize = DefaultSCIMPageSize
+	}
+	return &scimService{
+		repo:    repo,
+		scim:    scim,
+		users:   users,
+		tokens:  tokens,
+		options: options,
+		logger:  logger,
+		now:     time.Now,
+	}
+}
+
+var (
+	defaultSCIMMu sync.RWMutex
+	defaultSCIM   SCIMService
+)
+
+// SetDefaultSCIM 设置全局目录同步服务，启动时调用
+func SetDefaultSCIM(service SCIMService) {
+	defaultSCIMMu.Lock()
+	defer defaultSCIMMu.Unlock()
+	defaultSCIM = service
+}
+
+// DefaultSCIM 返回全局目录同步服务，未启用单点登录时返回nil
+func DefaultSCIM() SCIMService {
+	defaultSCIMMu.RLock()
+	defer defaultSCIMMu.RUnlock()
+	return defaultSCIM
+}
+
+// IssueToken 为身份提供方签发令牌，已有令牌立即失效
+func (s *scimService) IssueToken(ctx context.Context, providerID, adminID uint) (*SCIMTokenInfo, error) {
+	provider, err := s.provider(ctx, providerID)
+	if err != nil {
+		return nil, err
+	}
+	random, err := utils.GenerateHex(scimTokenLength)
+	if err != nil {
+		return nil, pkgErrors.WrapError(err, "生成令牌失败")
+	}
+	plain := SCIMTokenPrefix + random
+	token := &models.SCIMToken{
+		ProviderID:  provider.ID,
+		TokenHash:   hashSCIMToken(plain),
+		TokenPrefix: plain[:scimTokenDisplayLength],
+		CreatedBy:   adminID,
+	}
+	if err := s.scim.SaveToken(ctx, token); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("SCIM token issued",
+		zap.Uint("provider_id", provider.ID),
+		zap.String("tenant", provider.Tenant),
+		zap.Uint("admin_id", adminID))
+	info := newSCIMTokenInfo(token)
+	info.Token = plain
+	info.CreatedAt = s.now()
+	return info, nil
+}
+
+// GetToken 获取身份提供方的令牌信息(不含明文)
+func (s *scimService) GetToken(ctx context.Context, providerID uint) (*SCIMTokenInfo, error) {
+	if _, err := s.provider(ctx, providerID); err != nil {
+		return nil, err
+	}
+	token, err := s.scim.GetTokenByProvider(ctx, providerID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "尚未签发目录同步令牌")
+		}
+		return nil, pkgErrors.WrapError(err, "获取目录同步令牌失败")
+	}
+	return newSCIMTokenInfo(token), nil
+}
+
+// RevokeToken 吊销身份提供方的令牌，已同步的用户和组保留
+func (s *scimService) RevokeToken(ctx context.Context, providerID uint) error {
+	if _, err := s.GetToken(ctx, providerID); err != nil {
+		return err
+	}
+	if err := s.scim.DeleteToken(ctx, providerID); err != nil {
+		return err
+	}
+	s.logger.Info("SCIM token revoked", zap.Uint("provider_id", providerID))
+	return nil
+}
+
+// Authenticate 按令牌找到身份提供方，令牌无效或身份提供方未启用时返回 ErrSCIMUnauthorized
+func (s *scimService) Authenticate(ctx context.Context, token string) (*SCIMClient, error) {
+	if !strings.HasPrefix(token, SCIMTokenPrefix) {
+		return nil, pkgErrors.WrapError(ErrSCIMUnauthorized, "令牌无效")
+	}
+	record, err := s.scim.GetTokenByHash(ctx, hashSCIMToken(token))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.WrapError(ErrSCIMUnauthorized, "令牌无效")
+		}
+		return nil, pkgErrors.WrapError(err, "查询目录同步令牌失败")
+	}
+	provider, err := s.repo.GetProvider(ctx, record.ProviderID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.WrapError(ErrSCIMUnauthorized, "身份提供方不存在")
+		}
+		return nil, pkgErrors.WrapError(err, "获取身份提供方失败")
+	}
+	if !provider.IsActive {
+		return nil, pkgErrors.WrapError(ErrSCIMUnauthorized, "身份提供方未启用")
+	}
+
+	now := s.now()
+	if record.LastUsedAt == nil || now.Sub(*record.LastUsedAt) >= scimTouchInterval {
+		if err := s.scim.TouchToken(ctx, record.ID, now); err != nil {
+			s.logger.Warn("Failed to record SCIM token usage", zap.Uint("provider_id", provider.ID), zap.Error(err))
+		}
+	}
+	return &SCIMClient{ProviderID: provider.ID, Tenant: provider.Tenant, OwnerID: record.CreatedBy}, nil
+}
+
+// provider 获取身份提供方
+func (s *scimService) provider(ctx context.Context, id uint) (*models.SSOProvider, error) {
+	provider, err := s.repo.GetProvider(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "身份提供方不存在")
+		}
+		return nil, pkgErrors.WrapError(err, "获取身份提供方失败")
+	}
+	return provider, nil
+}
+
+// page 将SCIM分页参数转换为偏移量和数量
+func (s *scimService) page(query *SCIMListQuery) (startIndex, offset, limit int) {
+	startIndex = max(query.StartIndex, 1)
+	limit = query.Count
+	if limit < 0 || limit > s.options.PageSize {
+		limit = s.options.PageSize
+	}
+	return startIndex, startIndex - 1, limit
+}
+
+// hashSCIMToken 计算令牌的SHA-256(十六进制)
+func hashSCIMToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// newSCIMTokenInfo 生成令牌信息
+func newSCIMTokenInfo(token *models.SCIMToken) *SCIMTokenInfo {
+	return &SCIMTokenInfo{
+		TokenPrefix: token.TokenPrefix,
+		CreatedBy:   token.CreatedBy,
+		CreatedAt:   token.UpdatedAt,
+		LastUsedAt:  token.LastUsedAt,
+	}
+}
+
+// parseSCIMFilter 解析 attr eq "value" 形式的过滤表达式，属性名转为小写
+func parseSCIMFilter(filter string) (attr, value string, err error) {
+	filter = strings.TrimSpace(filter)
+	if filter == "" {
+		return "", "", nil
+	}
+	fields := strings.SplitN(filter, " ", 3)
+	if len(fields) != 3 || !strings.EqualFold(fields[1], "eq") {
+		return "", "", pkgErrors.WrapError(ErrSCIMInvalidFilter, "只支持 attr eq \"value\" 形式的过滤")
+	}
+	raw := strings.TrimSpace(fields[2])
+	if err := json.Unmarshal([]byte(raw), &value); err != nil {
+		return "", "", pkgErrors.WrapError(ErrSCIMInvalidFilter, "过滤值必须是字符串")
+	}
+	return strings.ToLower(fields[0]), value, nil
+}
+
+// parseSCIMBool 解析布尔值，兼容Azure AD以字符串发送的"True"/"False"
+func parseSCIMBool(raw json.RawMessage) (bool, error) {
+	var value bool
+	if err := json.Unmarshal(raw, &value); err == nil {
+		return value, nil
+	}
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		switch strings.ToLower(text) {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+	}
+	return false, pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "active必须是布尔值")
+}
+
+// parseSCIMString 解析字符串值，null视为空
+func parseSCIMString(raw json.RawMessage, attr string) (string, error) {
+	var value *string
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return "", pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "%s必须是字符串", attr)
+	}
+	if value == nil {
+		return "", nil
+	}
+	return *value, nil
+}
+
+// scimMeta 生成资源元数据
+func scimMeta(resourceType string, created, modified time.Time) *SCIMMeta {
+	return &SCIMMeta{ResourceType: resourceType, Created: &created, LastModified: &modified}
+}
@@ -0,0 +1,402 @@
+package sso
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/repository/models"
+	userrepo "cloudpan/internal/repository/user"
+)
+
+// maxGroupNameLength 组名称(团队名称)最大长度
+const maxGroupNameLength = 255
+
+// scimGroupState PATCH过程中的组属性，成员为本地用户ID
+type scimGroupState struct {
+	displayName string
+	externalID  string
+	members     []uint
+}
+
+// ListGroups 查询身份提供方同步的组，支持按displayName和externalId过滤
+func (s *scimService) ListGroups(ctx context.Context, client *SCIMClient, query *SCIMListQuery) (*SCIMListResponse, error) {
+	attr, value, err := parseSCIMFilter(query.Filter)
+	if err != nil {
+		return nil, err
+	}
+	var filter userrepo.SCIMGroupQuery
+	switch attr {
+	case "":
+	case "displayname":
+		filter.DisplayName = value
+	case "externalid":
+		filter.ExternalID = value
+	default:
+		return nil, pkgErrors.WrapErrorf(ErrSCIMInvalidFilter, "不支持按%s过滤", attr)
+	}
+
+	startIndex, offset, limit := s.page(query)
+	filter.Offset, filter.Limit = offset, limit
+	groups, total, err := s.scim.ListGroups(ctx, client.ProviderID, filter)
+	if err != nil {
+		return nil, err
+	}
+	resources := make([]*SCIMGroup, 0, len(groups))
+	for _, group := range groups {
+		var members []*models.TeamMember
+		if !query.ExcludeMembers {
+			if members, err = s.scim.ListGroupMembers(ctx, group.TeamID); err != nil {
+				return nil, err
+			}
+		}
+		resources = append(resources, toSCIMGroup(group, members))
+	}
+	return &SCIMListResponse{
+		Schemas:      []string{SCIMSchemaListResponse},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	}, nil
+}
+
+// GetGroup 获取身份提供方同步的组及其成员
+func (s *scimService) GetGroup(ctx context.Context, client *SCIMClient, id string) (*SCIMGroup, error) {
+	group, err := s.group(ctx, client, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.groupResource(ctx, group)
+}
+
+// CreateGroup 创建组及其对应的团队，团队归签发令牌的管理员所有
+func (s *scimService) CreateGroup(ctx context.Context, client *SCIMClient, req *SCIMGroup) (*SCIMGroup, error) {
+	name, err := normalizeGroupName(req.DisplayName)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.ensureUniqueGroup(ctx, client, name, req.ExternalID, 0); err != nil {
+		return nil, err
+	}
+	members, err := s.resolveMembers(ctx, client, req.Members, true)
+	if err != nil {
+		return nil, err
+	}
+	if len(members) > s.options.MaxGroupMembers {
+		return nil, pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "组成员不能超过%d个", s.options.MaxGroupMembers)
+	}
+
+	group := &models.SCIMGroup{
+		ProviderID:  client.ProviderID,
+		DisplayName: name,
+		ExternalID:  optionalString(req.ExternalID),
+		Team: models.Team{
+			Name:       name,
+			OwnerID:    client.OwnerID,
+			MaxMembers: s.options.MaxGroupMembers,
+			Status:     "active",
+		},
+	}
+	if err := s.scim.CreateGroup(ctx, group); err != nil {
+		return nil, err
+	}
+	if err := s.scim.AddGroupMembers(ctx, group.TeamID, members); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("SCIM group created",
+		zap.Uint("provider_id", client.ProviderID),
+		zap.Uint("team_id", group.TeamID),
+		zap.String("display_name", name),
+		zap.Int("members", len(members)))
+	return s.groupResource(ctx, group)
+}
+
+// ReplaceGroup 按请求替换组名称和成员
+func (s *scimService) ReplaceGroup(ctx context.Context, client *SCIMClient, id string, req *SCIMGroup) (*SCIMGroup, error) {
+	group, err := s.group(ctx, client, id)
+	if err != nil {
+		return nil, err
+	}
+	members, err := s.resolveMembers(ctx, client, req.Members, true)
+	if err != nil {
+		return nil, err
+	}
+	state := &scimGroupState{displayName: req.DisplayName, externalID: req.ExternalID, members: members}
+	if err := s.applyGroup(ctx, client, group, state); err != nil {
+		return nil, err
+	}
+	return s.groupResource(ctx, group)
+}
+
+// PatchGroup 按PATCH操作修改组名称或增删成员
+func (s *scimService) PatchGroup(ctx context.Context, client *SCIMClient, id string, req *SCIMPatchRequest) (*SCIMGroup, error) {
+	group, err := s.group(ctx, client, id)
+	if err != nil {
+		return nil, err
+	}
+	current, err := s.scim.ListGroupMembers(ctx, group.TeamID)
+	if err != nil {
+		return nil, err
+	}
+	state := &scimGroupState{displayName: group.DisplayName, externalID: stringValue(group.ExternalID)}
+	for _, member := range current {
+		state.members = append(state.members, member.UserID)
+	}
+
+	for _, op := range req.Operations {
+		if err := s.patchGroup(ctx, client, state, op); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.applyGroup(ctx, client, group, state); err != nil {
+		return nil, err
+	}
+	return s.groupResource(ctx, group)
+}
+
+// DeleteGroup 删除组，团队成员被移出，团队随之删除
+func (s *scimService) DeleteGroup(ctx context.Context, client *SCIMClient, id string) error {
+	group, err := s.group(ctx, client, id)
+	if err != nil {
+		return err
+	}
+	if err := s.scim.DeleteGroup(ctx, group); err != nil {
+		return err
+	}
+
+	s.logger.Info("SCIM group deleted",
+		zap.Uint("provider_id", client.ProviderID),
+		zap.Uint("team_id", group.TeamID),
+		zap.String("display_name", group.DisplayName))
+	return nil
+}
+
+// applyGroup 写入组名称并按差异增删团队成员
+func (s *scimService) applyGroup(ctx context.Context, client *SCIMClient, group *models.SCIMGroup, state *scimGroupState) error {
+	name, err := normalizeGroupName(state.displayName)
+	if err != nil {
+		return err
+	}
+	if err := s.ensureUniqueGroup(ctx, client, name, state.externalID, group.ID); err != nil {
+		return err
+	}
+	maxMembers := group.Team.MaxMembers
+	if maxMembers <= 0 {
+		maxMembers = s.options.MaxGroupMembers
+	}
+	if len(state.members) > maxMembers {
+		return pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "组成员不能超过%d个", maxMembers)
+	}
+
+	externalID := optionalString(state.externalID)
+	if name != group.DisplayName || stringValue(externalID) != stringValue(group.ExternalID) {
+		group.DisplayName = name
+		group.ExternalID = externalID
+		group.Team.Name = name
+		if err := s.scim.UpdateGroup(ctx, group); err != nil {
+			return err
+		}
+	}
+
+	current, err := s.scim.ListGroupMembers(ctx, group.TeamID)
+	if err != nil {
+		return err
+	}
+	var removed []uint
+	for _, member := range current {
+		if !slices.Contains(state.members, member.UserID) {
+			removed = append(removed, member.UserID)
+		}
+	}
+	if err := s.scim.RemoveGroupMembers(ctx, group.TeamID, removed); err != nil {
+		return err
+	}
+	return s.scim.AddGroupMembers(ctx, group.TeamID, state.members)
+}
+
+// patchGroup 将一个PATCH操作应用到组属性
+func (s *scimService) patchGroup(ctx context.Context, client *SCIMClient, state *scimGroupState, op SCIMPatchOperation) error {
+	remove, err := scimPatchRemove(op)
+	if err != nil {
+		return err
+	}
+	replace := strings.EqualFold(op.Op, "replace")
+	path := strings.ToLower(op.Path)
+
+	switch {
+	case path == "":
+		if remove {
+			return pkgErrors.WrapError(ErrSCIMInvalidPath, "remove操作必须指定path")
+		}
+		var attrs map[string]json.RawMessage
+		if err := json.Unmarshal(op.Value, &attrs); err != nil {
+			return pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "省略path时value必须是对象")
+		}
+		for attr, value := range attrs {
+			err := s.patchGroup(ctx, client, state, SCIMPatchOperation{Op: op.Op, Path: attr, Value: value})
+			if err != nil {
+				return err
+			}
+		}
+	case path == "displayname":
+		if remove {
+			return pkgErrors.WrapError(ErrSCIMInvalidPath, "displayName不能删除")
+		}
+		state.displayName, err = parseSCIMString(op.Value, "displayName")
+	case path == "externalid":
+		state.externalID = ""
+		if !remove {
+			state.externalID, err = parseSCIMString(op.Value, "externalId")
+		}
+	case path == "members":
+		var members []SCIMGroupMember
+		if len(op.Value) > 0 {
+			if err := json.Unmarshal(op.Value, &members); err != nil {
+				return pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "members格式错误")
+			}
+		}
+		switch {
+		case remove && len(members) == 0:
+			state.members = nil
+		case remove:
+			ids, err := s.resolveMembers(ctx, client, members, false)
+			if err != nil {
+				return err
+			}
+			state.members = slices.DeleteFunc(state.members, func(id uint) bool { return slices.Contains(ids, id) })
+		default:
+			ids, err := s.resolveMembers(ctx, client, members, true)
+			if err != nil {
+				return err
+			}
+			if replace {
+				state.members = nil
+			}
+			for _, id := range ids {
+				if !slices.Contains(state.members, id) {
+					state.members = append(state.members, id)
+				}
+			}
+		}
+	case strings.HasPrefix(path, "members[") && strings.HasSuffix(path, "]"):
+		// members[value eq "id"] 只支持删除单个成员
+		attr, value, err := parseSCIMFilter(op.Path[len("members[") : len(op.Path)-1])
+		if err != nil || attr != "value" || !remove {
+			return pkgErrors.WrapErrorf(ErrSCIMInvalidPath, "不支持的路径: %s", op.Path)
+		}
+		ids, err := s.resolveMembers(ctx, client, []SCIMGroupMember{{Value: value}}, false)
+		if err != nil {
+			return err
+		}
+		state.members = slices.DeleteFunc(state.members, func(id uint) bool { return slices.Contains(ids, id) })
+	default:
+		return pkgErrors.WrapErrorf(ErrSCIMInvalidPath, "不支持的路径: %s", op.Path)
+	}
+	return err
+}
+
+// resolveMembers 将成员UUID转换为本地用户ID
+//
+// strict为true时成员必须是该身份提供方同步的用户，否则返回 ErrInvalidInput；为false时忽略未知成员
+func (s *scimService) resolveMembers(ctx context.Context, client *SCIMClient, members []SCIMGroupMember, strict bool) ([]uint, error) {
+	uuids := make([]string, 0, len(members))
+	for _, member := range members {
+		if value := strings.TrimSpace(member.Value); value != "" && !slices.Contains(uuids, value) {
+			uuids = append(uuids, value)
+		}
+	}
+	links, err := s.scim.ListUsersByUUIDs(ctx, client.ProviderID, uuids)
+	if err != nil {
+		return nil, err
+	}
+	if strict && len(links) != len(uuids) {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "组成员不存在或不是该身份提供方同步的用户")
+	}
+	ids := make([]uint, 0, len(links))
+	for _, link := range links {
+		ids = append(ids, link.UserID)
+	}
+	return ids, nil
+}
+
+// ensureUniqueGroup 检查组名称和externalId在身份提供方内未被其他组使用
+func (s *scimService) ensureUniqueGroup(ctx context.Context, client *SCIMClient, name, externalID string, selfID uint) error {
+	groups, _, err := s.scim.ListGroups(ctx, client.ProviderID, userrepo.SCIMGroupQuery{DisplayName: name, Limit: 1})
+	if err != nil {
+		return err
+	}
+	if len(groups) > 0 && groups[0].ID != selfID {
+		return pkgErrors.WrapError(pkgErrors.ErrResourceExists, "组名称已存在")
+	}
+	if externalID = strings.TrimSpace(externalID); externalID == "" {
+		return nil
+	}
+	groups, _, err = s.scim.ListGroups(ctx, client.ProviderID, userrepo.SCIMGroupQuery{ExternalID: externalID, Limit: 1})
+	if err != nil {
+		return err
+	}
+	if len(groups) > 0 && groups[0].ID != selfID {
+		return pkgErrors.WrapError(pkgErrors.ErrResourceExists, "externalId已存在")
+	}
+	return nil
+}
+
+// group 按SCIM资源ID获取身份提供方同步的组
+func (s *scimService) group(ctx context.Context, client *SCIMClient, id string) (*models.SCIMGroup, error) {
+	group, err := s.scim.GetGroupByUUID(ctx, client.ProviderID, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "组不存在")
+		}
+		return nil, pkgErrors.WrapError(err, "获取目录同步组失败")
+	}
+	return group, nil
+}
+
+// groupResource 查询成员并生成组资源
+func (s *scimService) groupResource(ctx context.Context, group *models.SCIMGroup) (*SCIMGroup, error) {
+	members, err := s.scim.ListGroupMembers(ctx, group.TeamID)
+	if err != nil {
+		return nil, err
+	}
+	return toSCIMGroup(group, members), nil
+}
+
+// normalizeGroupName 校验组名称
+func normalizeGroupName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "displayName不能为空")
+	}
+	if len(name) > maxGroupNameLength {
+		return "", pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "displayName不能超过%d个字符", maxGroupNameLength)
+	}
+	return name, nil
+}
+
+// toSCIMGroup 生成组资源，成员显示名称取账户的显示名称或用户名
+func toSCIMGroup(group *models.SCIMGroup, members []*models.TeamMember) *SCIMGroup {
+	resource := &SCIMGroup{
+		Schemas:     []string{SCIMSchemaGroup},
+		ID:          group.Team.UUID,
+		ExternalID:  stringValue(group.ExternalID),
+		DisplayName: group.DisplayName,
+		Meta:        scimMeta("Group", group.CreatedAt, group.UpdatedAt),
+	}
+	for _, member := range members {
+		display := stringValue(member.User.DisplayName)
+		if display == "" {
+			display = member.User.Username
+		}
+		resource.Members = append(resource.Members, SCIMGroupMember{Value: member.User.UUID, Display: display})
+	}
+	return resource
+}
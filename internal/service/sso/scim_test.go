@@ -0,0 +1,488 @@
+package sso
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/cache"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/repository/models"
+	userrepo "cloudpan/internal/repository/user"
+)
+
+// memorySCIMRepository 内存实现的目录同步数据仓库，用户关联从 memoryUsers 加载账户
+type memorySCIMRepository struct {
+	users   *memoryUsers
+	tokens  map[uint]*models.SCIMToken
+	links   []*models.SCIMUser
+	groups  []*models.SCIMGroup
+	members map[uint][]uint
+	nextID  uint
+}
+
+func newMemorySCIMRepository(users *memoryUsers) *memorySCIMRepository {
+	return &memorySCIMRepository{users: users, tokens: make(map[uint]*models.SCIMToken), members: make(map[uint][]uint)}
+}
+
+func (r *memorySCIMRepository) id() uint {
+	r.nextID++
+	return r.nextID
+}
+
+func (r *memorySCIMRepository) SaveToken(_ context.Context, token *models.SCIMToken) error {
+	token.ID = r.id()
+	copied := *token
+	r.tokens[token.ProviderID] = &copied
+	return nil
+}
+
+func (r *memorySCIMRepository) GetTokenByHash(_ context.Context, hash string) (*models.SCIMToken, error) {
+	for _, token := range r.tokens {
+		if token.TokenHash == hash {
+			copied := *token
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memorySCIMRepository) GetTokenByProvider(_ context.Context, providerID uint) (*models.SCIMToken, error) {
+	if token, ok := r.tokens[providerID]; ok {
+		copied := *token
+		return &copied, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memorySCIMRepository) TouchToken(_ context.Context, id uint, usedAt time.Time) error {
+	for _, token := range r.tokens {
+		if token.ID == id {
+			token.LastUsedAt = &usedAt
+		}
+	}
+	return nil
+}
+
+func (r *memorySCIMRepository) DeleteToken(_ context.Context, providerID uint) error {
+	delete(r.tokens, providerID)
+	return nil
+}
+
+func (r *memorySCIMRepository) CreateUser(_ context.Context, link *models.SCIMUser) error {
+	link.ID = r.id()
+	copied := *link
+	copied.User = models.User{}
+	r.links = append(r.links, &copied)
+	return nil
+}
+
+func (r *memorySCIMRepository) UpdateUser(_ context.Context, link *models.SCIMUser) error {
+	for _, existing := range r.links {
+		if existing.ID == link.ID {
+			existing.UserName = link.UserName
+			existing.ExternalID = link.ExternalID
+		}
+	}
+	return nil
+}
+
+func (r *memorySCIMRepository) DeleteUser(_ context.Context, id uint) error {
+	r.links = slices.DeleteFunc(r.links, func(link *models.SCIMUser) bool { return link.ID == id })
+	return nil
+}
+
+// loaded 复制用户关联并加载账户
+func (r *memorySCIMRepository) loaded(link *models.SCIMUser) *models.SCIMUser {
+	copied := *link
+	copied.User = *r.users.users[link.UserID]
+	return &copied
+}
+
+func (r *memorySCIMRepository) GetUserByUUID(_ context.Context, providerID uint, uuid string) (*models.SCIMUser, error) {
+	for _, link := range r.links {
+		if link.ProviderID == providerID && r.users.users[link.UserID].UUID == uuid {
+			return r.loaded(link), nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memorySCIMRepository) GetUserByUserID(_ context.Context, providerID, userID uint) (*models.SCIMUser, error) {
+	for _, link := range r.links {
+		if link.ProviderID == providerID && link.UserID == userID {
+			return r.loaded(link), nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memorySCIMRepository) ListUsers(_ context.Context, providerID uint, query userrepo.SCIMUserQuery) ([]*models.SCIMUser, int64, error) {
+	var matched []*models.SCIMUser
+	for _, link := range r.links {
+		if link.ProviderID != providerID ||
+			(query.UserName != "" && link.UserName != query.UserName) ||
+			(query.ExternalID != "" && stringValue(link.ExternalID) != query.ExternalID) ||
+			(query.Email != "" && r.users.users[link.UserID].Email != query.Email) {
+			continue
+		}
+		matched = append(matched, r.loaded(link))
+	}
+	total := int64(len(matched))
+	matched = matched[min(query.Offset, len(matched)):]
+	return matched[:min(query.Limit, len(matched))], total, nil
+}
+
+func (r *memorySCIMRepository) ListUsersByUUIDs(ctx context.Context, providerID uint, uuids []string) ([]*models.SCIMUser, error) {
+	var links []*models.SCIMUser
+	for _, uuid := range uuids {
+		if link, err := r.GetUserByUUID(ctx, providerID, uuid); err == nil {
+			links = append(links, link)
+		}
+	}
+	return links, nil
+}
+
+func (r *memorySCIMRepository) CreateGroup(_ context.Context, group *models.SCIMGroup) error {
+	group.Team.ID = r.id()
+	group.Team.UUID = fmt.Sprintf("team-%d", group.Team.ID)
+	group.TeamID = group.Team.ID
+	group.ID = r.id()
+	copied := *group
+	r.groups = append(r.groups, &copied)
+	return nil
+}
+
+func (r *memorySCIMRepository) UpdateGroup(_ context.Context, group *models.SCIMGroup) error {
+	for _, existing := range r.groups {
+		if existing.ID == group.ID {
+			existing.DisplayName = group.DisplayName
+			existing.ExternalID = group.ExternalID
+			existing.Team.Name = group.DisplayName
+		}
+	}
+	return nil
+}
+
+func (r *memorySCIMRepository) DeleteGroup(_ context.Context, group *models.SCIMGroup) error {
+	r.groups = slices.DeleteFunc(r.groups, func(g *models.SCIMGroup) bool { return g.ID == group.ID })
+	delete(r.members, group.TeamID)
+	return nil
+}
+
+func (r *memorySCIMRepository) GetGroupByUUID(_ context.Context, providerID uint, uuid string) (*models.SCIMGroup, error) {
+	for _, group := range r.groups {
+		if group.ProviderID == providerID && group.Team.UUID == uuid {
+			copied := *group
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memorySCIMRepository) ListGroups(_ context.Context, providerID uint, query userrepo.SCIMGroupQuery) ([]*models.SCIMGroup, int64, error) {
+	var matched []*models.SCIMGroup
+	for _, group := range r.groups {
+		if group.ProviderID != providerID ||
+			(query.DisplayName != "" && group.DisplayName != query.DisplayName) ||
+			(query.ExternalID != "" && stringValue(group.ExternalID) != query.ExternalID) {
+			continue
+		}
+		copied := *group
+		matched = append(matched, &copied)
+	}
+	total := int64(len(matched))
+	matched = matched[min(query.Offset, len(matched)):]
+	return matched[:min(query.Limit, len(matched))], total, nil
+}
+
+func (r *memorySCIMRepository) ListGroupMembers(_ context.Context, teamID uint) ([]*models.TeamMember, error) {
+	var members []*models.TeamMember
+	for _, userID := range r.members[teamID] {
+		members = append(members, &models.TeamMember{TeamID: teamID, UserID: userID, User: *r.users.users[userID]})
+	}
+	return members, nil
+}
+
+func (r *memorySCIMRepository) AddGroupMembers(_ context.Context, teamID uint, userIDs []uint) error {
+	for _, userID := range userIDs {
+		if !slices.Contains(r.members[teamID], userID) {
+			r.members[teamID] = append(r.members[teamID], userID)
+		}
+	}
+	return nil
+}
+
+func (r *memorySCIMRepository) RemoveGroupMembers(_ context.Context, teamID uint, userIDs []uint) error {
+	r.members[teamID] = slices.DeleteFunc(r.members[teamID], func(id uint) bool { return slices.Contains(userIDs, id) })
+	return nil
+}
+
+func (r *memorySCIMRepository) RemoveUserFromGroups(ctx context.Context, providerID, userID uint) error {
+	for _, group := range r.groups {
+		if group.ProviderID == providerID {
+			_ = r.RemoveGroupMembers(ctx, group.TeamID, []uint{userID})
+		}
+	}
+	return nil
+}
+
+type scimFixture struct {
+	*ssoFixture
+	scim    *memorySCIMRepository
+	tokens  *cache.MemoryTokenStore
+	service SCIMService
+	client  *SCIMClient
+}
+
+// newSCIMFixture 创建认领并验证了 acme.com 的身份提供方，签发令牌并完成认证
+func newSCIMFixture(t *testing.T, options SCIMOptions) *scimFixture {
+	t.Helper()
+	f := newSSOFixture(t)
+	provider := f.setupProvider(t, nil)
+	scim := newMemorySCIMRepository(f.users)
+	tokens := cache.NewMemoryTokenStore(time.Hour)
+	service := NewSCIMService(f.repo, scim, f.users, tokens, options, zap.NewNop())
+
+	token, err := service.IssueToken(context.Background(), provider.ID, 1)
+	require.NoError(t, err)
+	client, err := service.Authenticate(context.Background(), token.Token)
+	require.NoError(t, err)
+	return &scimFixture{ssoFixture: f, scim: scim, tokens: tokens, service: service, client: client}
+}
+
+func (f *scimFixture) createUser(t *testing.T, userName string) *SCIMUser {
+	t.Helper()
+	user, err := f.service.CreateUser(context.Background(), f.client, &SCIMUser{UserName: userName, DisplayName: userName})
+	require.NoError(t, err)
+	return user
+}
+
+func patchOp(op, path string, value interface{}) SCIMPatchOperation {
+	raw, _ := json.Marshal(value)
+	return SCIMPatchOperation{Op: op, Path: path, Value: raw}
+}
+
+func TestSCIMService_Tokens(t *testing.T) {
+	ctx := context.Background()
+	f := newSCIMFixture(t, SCIMOptions{})
+	assert.Equal(t, "acme", f.client.Tenant)
+	assert.Equal(t, uint(1), f.client.OwnerID)
+
+	_, err := f.service.Authenticate(ctx, "scim_not-a-token")
+	assert.ErrorIs(t, err, ErrSCIMUnauthorized)
+	_, err = f.service.Authenticate(ctx, "Bearer xyz")
+	assert.ErrorIs(t, err, ErrSCIMUnauthorized)
+
+	// 重新签发后旧令牌失效
+	first, err := f.service.IssueToken(ctx, f.client.ProviderID, 2)
+	require.NoError(t, err)
+	second, err := f.service.IssueToken(ctx, f.client.ProviderID, 2)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.Token, second.Token)
+	_, err = f.service.Authenticate(ctx, first.Token)
+	assert.ErrorIs(t, err, ErrSCIMUnauthorized)
+	_, err = f.service.Authenticate(ctx, second.Token)
+	require.NoError(t, err)
+
+	info, err := f.service.GetToken(ctx, f.client.ProviderID)
+	require.NoError(t, err)
+	assert.Empty(t, info.Token, "查询不返回明文令牌")
+	assert.Equal(t, second.Token[:scimTokenDisplayLength], info.TokenPrefix)
+	assert.NotNil(t, info.LastUsedAt)
+
+	// 身份提供方停用后令牌不可用
+	provider := f.repo.providers[f.client.ProviderID]
+	provider.IsActive = false
+	_, err = f.service.Authenticate(ctx, second.Token)
+	assert.ErrorIs(t, err, ErrSCIMUnauthorized)
+	provider.IsActive = true
+
+	require.NoError(t, f.service.RevokeToken(ctx, f.client.ProviderID))
+	_, err = f.service.Authenticate(ctx, second.Token)
+	assert.ErrorIs(t, err, ErrSCIMUnauthorized)
+	assert.True(t, pkgErrors.IsNotFoundError(f.service.RevokeToken(ctx, f.client.ProviderID)))
+	_, err = f.service.IssueToken(ctx, 999, 1)
+	assert.True(t, pkgErrors.IsNotFoundError(err))
+}
+
+func TestSCIMService_CreateUserConflicts(t *testing.T) {
+	ctx := context.Background()
+	f := newSCIMFixture(t, SCIMOptions{})
+
+	_, err := f.service.CreateUser(ctx, f.client, &SCIMUser{UserName: "mallory@other.com"})
+	assert.True(t, pkgErrors.IsValidationError(err), "未认证域名的邮箱不能同步")
+	_, err = f.service.CreateUser(ctx, f.client, &SCIMUser{UserName: "alice"})
+	assert.True(t, pkgErrors.IsValidationError(err), "缺少邮箱")
+
+	alice, err := f.service.CreateUser(ctx, f.client, &SCIMUser{
+		UserName:   "Alice@acme.com",
+		ExternalID: "00u1",
+		Name:       &SCIMName{GivenName: "Alice", FamilyName: "Liddell"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "alice@acme.com", alice.UserName)
+	assert.Equal(t, "Alice Liddell", alice.DisplayName)
+	assert.True(t, *alice.Active)
+	assert.Equal(t, "alice@acme.com", alice.Emails[0].Value)
+
+	_, err = f.service.CreateUser(ctx, f.client, &SCIMUser{UserName: "ALICE@acme.com"})
+	assert.ErrorIs(t, err, pkgErrors.ErrResourceExists, "userName重复")
+	_, err = f.service.CreateUser(ctx, f.client, &SCIMUser{UserName: "alice2@acme.com", ExternalID: "00u1"})
+	assert.ErrorIs(t, err, pkgErrors.ErrResourceExists, "externalId重复")
+	_, err = f.service.CreateUser(ctx, f.client, &SCIMUser{UserName: "a.liddell", Emails: []SCIMEmail{{Value: "alice@acme.com", Primary: true}}})
+	assert.ErrorIs(t, err, pkgErrors.ErrResourceExists, "账户已由该身份提供方同步")
+
+	// 邮箱已注册的账户直接关联，不再开通
+	existing := &models.User{Email: "bob@acme.com", Username: "bob", Status: "suspended"}
+	require.NoError(t, f.users.Create(ctx, existing))
+	bob, err := f.service.CreateUser(ctx, f.client, &SCIMUser{UserName: "bob@acme.com"})
+	require.NoError(t, err)
+	assert.Equal(t, existing.UUID, bob.ID)
+	assert.False(t, *bob.Active, "封禁的账户不因关联而恢复")
+	assert.Len(t, f.users.users, 2)
+
+	deleting := &models.User{Email: "carol@acme.com", Username: "carol", Status: "deleted"}
+	require.NoError(t, f.users.Create(ctx, deleting))
+	_, err = f.service.CreateUser(ctx, f.client, &SCIMUser{UserName: "carol@acme.com"})
+	assert.ErrorIs(t, err, pkgErrors.ErrResourceExists, "正在注销的账户不能关联")
+
+	list, err := f.service.ListUsers(ctx, f.client, &SCIMListQuery{Filter: `userName eq "ALICE@acme.com"`, Count: -1})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), list.TotalResults)
+	assert.Equal(t, alice.ID, list.Resources.([]*SCIMUser)[0].ID)
+	_, err = f.service.ListUsers(ctx, f.client, &SCIMListQuery{Filter: `title co "x"`})
+	assert.ErrorIs(t, err, ErrSCIMInvalidFilter)
+}
+
+func TestSCIMService_UserLifecycle(t *testing.T) {
+	ctx := context.Background()
+	f := newSCIMFixture(t, SCIMOptions{})
+	alice := f.createUser(t, "alice@acme.com")
+	bob := f.createUser(t, "bob@acme.com")
+	group, err := f.service.CreateGroup(ctx, f.client, &SCIMGroup{DisplayName: "Engineering", Members: []SCIMGroupMember{{Value: alice.ID}}})
+	require.NoError(t, err)
+	account := func() *models.User { return f.users.users[1] }
+
+	// Azure AD以字符串发送布尔值
+	patched, err := f.service.PatchUser(ctx, f.client, alice.ID, &SCIMPatchRequest{Operations: []SCIMPatchOperation{
+		patchOp("Replace", "active", "False"),
+		patchOp("replace", "", map[string]interface{}{"displayName": "Alice L", "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:department": "R&D"}),
+	}})
+	require.NoError(t, err)
+	assert.False(t, *patched.Active)
+	assert.Equal(t, "Alice L", patched.DisplayName)
+	assert.Equal(t, "inactive", account().Status)
+	revokedAt, err := f.tokens.RevokedBefore(ctx, uint64(account().ID))
+	require.NoError(t, err)
+	assert.False(t, revokedAt.IsZero(), "停用时吊销登录令牌")
+
+	patched, err = f.service.PatchUser(ctx, f.client, alice.ID, &SCIMPatchRequest{Operations: []SCIMPatchOperation{patchOp("replace", "active", true)}})
+	require.NoError(t, err)
+	assert.True(t, *patched.Active)
+	assert.Equal(t, "active", account().Status)
+
+	// 修改邮箱不能与其他账户冲突
+	_, err = f.service.ReplaceUser(ctx, f.client, alice.ID, &SCIMUser{UserName: "alice@acme.com", Emails: []SCIMEmail{{Value: "bob@acme.com"}}})
+	assert.ErrorIs(t, err, pkgErrors.ErrResourceExists)
+	_, err = f.service.ReplaceUser(ctx, f.client, alice.ID, &SCIMUser{UserName: "bob@acme.com"})
+	assert.ErrorIs(t, err, pkgErrors.ErrResourceExists)
+	replaced, err := f.service.ReplaceUser(ctx, f.client, alice.ID, &SCIMUser{
+		UserName: "alice@acme.com",
+		Emails:   []SCIMEmail{{Value: "alice.l@acme.com", Primary: true}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "alice.l@acme.com", replaced.Emails[0].Value)
+	assert.Equal(t, "alice.l@acme.com", account().Email)
+	assert.True(t, *replaced.Active, "省略active不改变状态")
+
+	// 删除后停用账户并移出团队
+	require.NoError(t, f.service.DeleteUser(ctx, f.client, alice.ID))
+	assert.Equal(t, "inactive", account().Status)
+	_, err = f.service.GetUser(ctx, f.client, alice.ID)
+	assert.True(t, pkgErrors.IsNotFoundError(err))
+	fetched, err := f.service.GetGroup(ctx, f.client, group.ID)
+	require.NoError(t, err)
+	assert.Empty(t, fetched.Members)
+	_, err = f.service.GetUser(ctx, f.client, bob.ID)
+	assert.NoError(t, err)
+}
+
+func TestSCIMService_Groups(t *testing.T) {
+	ctx := context.Background()
+	f := newSCIMFixture(t, SCIMOptions{MaxGroupMembers: 2})
+	alice := f.createUser(t, "alice@acme.com")
+	bob := f.createUser(t, "bob@acme.com")
+	carol := f.createUser(t, "carol@acme.com")
+	outsider := &models.User{Email: "dave@acme.com", Username: "dave", Status: "active"}
+	require.NoError(t, f.users.Create(ctx, outsider))
+
+	_, err := f.service.CreateGroup(ctx, f.client, &SCIMGroup{DisplayName: "Eng", Members: []SCIMGroupMember{{Value: outsider.UUID}}})
+	assert.True(t, pkgErrors.IsValidationError(err), "成员必须由同一身份提供方同步")
+	_, err = f.service.CreateGroup(ctx, f.client, &SCIMGroup{DisplayName: "Eng", Members: []SCIMGroupMember{{Value: alice.ID}, {Value: bob.ID}, {Value: carol.ID}}})
+	assert.True(t, pkgErrors.IsValidationError(err), "超过成员上限")
+
+	group, err := f.service.CreateGroup(ctx, f.client, &SCIMGroup{DisplayName: "Eng", ExternalID: "g1", Members: []SCIMGroupMember{{Value: alice.ID}}})
+	require.NoError(t, err)
+	assert.Equal(t, []SCIMGroupMember{{Value: alice.ID, Display: "alice@acme.com"}}, group.Members)
+	assert.Equal(t, uint(1), f.scim.groups[0].Team.OwnerID, "团队归签发令牌的管理员所有")
+	assert.Equal(t, 2, f.scim.groups[0].Team.MaxMembers)
+	_, err = f.service.CreateGroup(ctx, f.client, &SCIMGroup{DisplayName: "Eng"})
+	assert.ErrorIs(t, err, pkgErrors.ErrResourceExists)
+
+	// Okta按成员增删，Azure AD按路径过滤删除
+	patched, err := f.service.PatchGroup(ctx, f.client, group.ID, &SCIMPatchRequest{Operations: []SCIMPatchOperation{
+		patchOp("add", "members", []SCIMGroupMember{{Value: bob.ID}}),
+		patchOp("Remove", fmt.Sprintf(`members[value eq "%s"]`, alice.ID), nil),
+		patchOp("replace", "displayName", "Engineering"),
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, "Engineering", patched.DisplayName)
+	assert.Equal(t, []SCIMGroupMember{{Value: bob.ID, Display: "bob@acme.com"}}, patched.Members)
+	assert.Equal(t, "Engineering", f.scim.groups[0].Team.Name)
+
+	_, err = f.service.PatchGroup(ctx, f.client, group.ID, &SCIMPatchRequest{Operations: []SCIMPatchOperation{
+		patchOp("add", "members", []SCIMGroupMember{{Value: alice.ID}, {Value: carol.ID}}),
+	}})
+	assert.True(t, pkgErrors.IsValidationError(err), "超过成员上限")
+	_, err = f.service.PatchGroup(ctx, f.client, group.ID, &SCIMPatchRequest{Operations: []SCIMPatchOperation{patchOp("replace", "owner", "x")}})
+	assert.ErrorIs(t, err, ErrSCIMInvalidPath)
+
+	replaced, err := f.service.ReplaceGroup(ctx, f.client, group.ID, &SCIMGroup{DisplayName: "Engineering", Members: []SCIMGroupMember{{Value: carol.ID}}})
+	require.NoError(t, err)
+	assert.Equal(t, carol.ID, replaced.Members[0].Value)
+	assert.Len(t, replaced.Members, 1)
+
+	list, err := f.service.ListGroups(ctx, f.client, &SCIMListQuery{Filter: `displayName eq "Engineering"`, Count: 10, ExcludeMembers: true})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), list.TotalResults)
+	assert.Empty(t, list.Resources.([]*SCIMGroup)[0].Members)
+
+	require.NoError(t, f.service.DeleteGroup(ctx, f.client, group.ID))
+	_, err = f.service.GetGroup(ctx, f.client, group.ID)
+	assert.True(t, pkgErrors.IsNotFoundError(err))
+}
+
+func TestParseSCIMFilter(t *testing.T) {
+	attr, value, err := parseSCIMFilter(`userName Eq "alice smith@acme.com"`)
+	require.NoError(t, err)
+	assert.Equal(t, "username", attr)
+	assert.Equal(t, "alice smith@acme.com", value)
+
+	attr, _, err = parseSCIMFilter("  ")
+	require.NoError(t, err)
+	assert.Empty(t, attr)
+
+	for _, filter := range []string{`userName sw "a"`, `userName eq alice`, `userName`} {
+		_, _, err := parseSCIMFilter(filter)
+		assert.ErrorIs(t, err, ErrSCIMInvalidFilter, filter)
+	}
+}
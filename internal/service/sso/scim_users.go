@@ -0,0 +1,484 @@
+package sso
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/repository/models"
+	userrepo "cloudpan/internal/repository/user"
+)
+
+// 本地账户状态
+const (
+	accountStatusActive   = "active"
+	accountStatusInactive = "inactive"
+	accountStatusDeleted  = "deleted"
+)
+
+// ListUsers 查询身份提供方同步的用户，支持按userName、externalId和邮箱过滤
+func (s *scimService) ListUsers(ctx context.Context, client *SCIMClient, query *SCIMListQuery) (*SCIMListResponse, error) {
+	attr, value, err := parseSCIMFilter(query.Filter)
+	if err != nil {
+		return nil, err
+	}
+	var filter userrepo.SCIMUserQuery
+	switch attr {
+	case "":
+	case "username":
+		filter.UserName = strings.ToLower(value)
+	case "externalid":
+		filter.ExternalID = value
+	case "emails", "emails.value":
+		filter.Email = strings.ToLower(value)
+	default:
+		return nil, pkgErrors.WrapErrorf(ErrSCIMInvalidFilter, "不支持按%s过滤", attr)
+	}
+
+	startIndex, offset, limit := s.page(query)
+	filter.Offset, filter.Limit = offset, limit
+	links, total, err := s.scim.ListUsers(ctx, client.ProviderID, filter)
+	if err != nil {
+		return nil, err
+	}
+	resources := make([]*SCIMUser, 0, len(links))
+	for _, link := range links {
+		resources = append(resources, toSCIMUser(link))
+	}
+	return &SCIMListResponse{
+		Schemas:      []string{SCIMSchemaListResponse},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	}, nil
+}
+
+// GetUser 获取身份提供方同步的用户
+func (s *scimService) GetUser(ctx context.Context, client *SCIMClient, id string) (*SCIMUser, error) {
+	link, err := s.userLink(ctx, client, id)
+	if err != nil {
+		return nil, err
+	}
+	return toSCIMUser(link), nil
+}
+
+// CreateUser 开通账户，邮箱已注册时关联已有账户
+//
+// 已由该身份提供方同步的账户和正在注销的账户不能关联，返回 ErrResourceExists
+func (s *scimService) CreateUser(ctx context.Context, client *SCIMClient, req *SCIMUser) (*SCIMUser, error) {
+	userName, email, err := normalizeSCIMUser(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkEmailDomain(ctx, client, email); err != nil {
+		return nil, err
+	}
+	if err := s.ensureUniqueUser(ctx, client, userName, req.ExternalID, 0); err != nil {
+		return nil, err
+	}
+
+	account, err := s.users.GetByEmail(ctx, email)
+	adopted := err == nil
+	switch {
+	case adopted:
+		if _, err := s.scim.GetUserByUserID(ctx, client.ProviderID, account.ID); err == nil {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceExists, "该邮箱的账户已由身份提供方同步")
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.WrapError(err, "查询目录同步用户失败")
+		}
+		if account.Status == accountStatusDeleted {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceExists, "该邮箱的账户正在注销")
+		}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		account, err = provisionAccount(ctx, s.users, client.Tenant, email, scimDisplayName(req), s.options.StorageQuota, s.now())
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, pkgErrors.WrapError(err, "获取用户失败")
+	}
+
+	link := &models.SCIMUser{
+		ProviderID: client.ProviderID,
+		UserID:     account.ID,
+		UserName:   userName,
+		ExternalID: optionalString(req.ExternalID),
+	}
+	if err := s.scim.CreateUser(ctx, link); err != nil {
+		return nil, err
+	}
+	link.User = *account
+	if req.Active != nil {
+		if err := s.setActive(ctx, &link.User, *req.Active); err != nil {
+			return nil, err
+		}
+	}
+
+	s.logger.Info("SCIM user created",
+		zap.Uint("provider_id", client.ProviderID),
+		zap.Uint("user_id", account.ID),
+		zap.String("user_name", userName),
+		zap.Bool("adopted", adopted))
+	return toSCIMUser(link), nil
+}
+
+// ReplaceUser 按请求替换用户属性，省略active时不改变账户状态
+func (s *scimService) ReplaceUser(ctx context.Context, client *SCIMClient, id string, req *SCIMUser) (*SCIMUser, error) {
+	link, err := s.userLink(ctx, client, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyUser(ctx, client, link, req); err != nil {
+		return nil, err
+	}
+	return toSCIMUser(link), nil
+}
+
+// PatchUser 按PATCH操作修改用户属性，不支持的属性被忽略
+func (s *scimService) PatchUser(ctx context.Context, client *SCIMClient, id string, req *SCIMPatchRequest) (*SCIMUser, error) {
+	link, err := s.userLink(ctx, client, id)
+	if err != nil {
+		return nil, err
+	}
+	resource := toSCIMUser(link)
+	for _, op := range req.Operations {
+		if err := patchSCIMUser(resource, op); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.applyUser(ctx, client, link, resource); err != nil {
+		return nil, err
+	}
+	return toSCIMUser(link), nil
+}
+
+// DeleteUser 停用账户并移出同步的团队，删除关联后账户保留
+func (s *scimService) DeleteUser(ctx context.Context, client *SCIMClient, id string) error {
+	link, err := s.userLink(ctx, client, id)
+	if err != nil {
+		return err
+	}
+	if err := s.setActive(ctx, &link.User, false); err != nil {
+		return err
+	}
+	if err := s.scim.RemoveUserFromGroups(ctx, client.ProviderID, link.UserID); err != nil {
+		return err
+	}
+	if err := s.scim.DeleteUser(ctx, link.ID); err != nil {
+		return err
+	}
+
+	s.logger.Info("SCIM user deleted",
+		zap.Uint("provider_id", client.ProviderID),
+		zap.Uint("user_id", link.UserID),
+		zap.String("user_name", link.UserName))
+	return nil
+}
+
+// applyUser 将资源写入用户关联和本地账户
+func (s *scimService) applyUser(ctx context.Context, client *SCIMClient, link *models.SCIMUser, req *SCIMUser) error {
+	userName, email, err := normalizeSCIMUser(req)
+	if err != nil {
+		return err
+	}
+	if err := s.ensureUniqueUser(ctx, client, userName, req.ExternalID, link.ID); err != nil {
+		return err
+	}
+
+	account := &link.User
+	changed := false
+	if email != account.Email {
+		if err := s.checkEmailDomain(ctx, client, email); err != nil {
+			return err
+		}
+		other, err := s.users.GetByEmail(ctx, email)
+		if err == nil && other.ID != account.ID {
+			return pkgErrors.WrapError(pkgErrors.ErrResourceExists, "邮箱已被其他账户使用")
+		}
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return pkgErrors.WrapError(err, "获取用户失败")
+		}
+		now := s.now()
+		account.Email = email
+		account.EmailVerified = true
+		account.EmailVerifiedAt = &now
+		changed = true
+	}
+	if name := scimDisplayName(req); name != stringValue(account.DisplayName) {
+		account.DisplayName = optionalString(name)
+		changed = true
+	}
+	if changed {
+		if err := s.users.Update(ctx, account); err != nil {
+			return pkgErrors.WrapError(err, "更新用户失败")
+		}
+	}
+
+	link.UserName = userName
+	link.ExternalID = optionalString(req.ExternalID)
+	if err := s.scim.UpdateUser(ctx, link); err != nil {
+		return err
+	}
+	if req.Active != nil {
+		return s.setActive(ctx, account, *req.Active)
+	}
+	return nil
+}
+
+// setActive 按目录状态停用或恢复账户
+//
+// 停用只作用于正常账户并吊销已签发的登录令牌；恢复只作用于停用的账户，
+// 被管理员封禁或正在注销的账户不受目录状态影响
+func (s *scimService) setActive(ctx context.Context, account *models.User, active bool) error {
+	switch {
+	case !active && account.Status == accountStatusActive:
+		account.Status = accountStatusInactive
+	case active && account.Status == accountStatusInactive:
+		account.Status = accountStatusActive
+	default:
+		return nil
+	}
+	if err := s.users.Update(ctx, account); err != nil {
+		return pkgErrors.WrapError(err, "更新账户状态失败")
+	}
+
+	if !active && s.tokens != nil {
+		if err := s.tokens.RevokeUserTokens(ctx, uint64(account.ID), s.now()); err != nil {
+			s.logger.Warn("Failed to revoke tokens of deactivated user", zap.Uint("user_id", account.ID), zap.Error(err))
+		}
+	}
+	s.logger.Info("SCIM user status changed",
+		zap.Uint("user_id", account.ID),
+		zap.String("status", account.Status))
+	return nil
+}
+
+// userLink 按SCIM资源ID获取身份提供方同步的用户
+func (s *scimService) userLink(ctx context.Context, client *SCIMClient, id string) (*models.SCIMUser, error) {
+	link, err := s.scim.GetUserByUUID(ctx, client.ProviderID, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "用户不存在")
+		}
+		return nil, pkgErrors.WrapError(err, "获取目录同步用户失败")
+	}
+	return link, nil
+}
+
+// ensureUniqueUser 检查userName和externalId在身份提供方内未被其他用户使用
+func (s *scimService) ensureUniqueUser(ctx context.Context, client *SCIMClient, userName, externalID string, selfID uint) error {
+	links, _, err := s.scim.ListUsers(ctx, client.ProviderID, userrepo.SCIMUserQuery{UserName: userName, Limit: 1})
+	if err != nil {
+		return err
+	}
+	if len(links) > 0 && links[0].ID != selfID {
+		return pkgErrors.WrapError(pkgErrors.ErrResourceExists, "userName已存在")
+	}
+	if externalID == "" {
+		return nil
+	}
+	links, _, err = s.scim.ListUsers(ctx, client.ProviderID, userrepo.SCIMUserQuery{ExternalID: externalID, Limit: 1})
+	if err != nil {
+		return err
+	}
+	if len(links) > 0 && links[0].ID != selfID {
+		return pkgErrors.WrapError(pkgErrors.ErrResourceExists, "externalId已存在")
+	}
+	return nil
+}
+
+// checkEmailDomain 检查邮箱属于该身份提供方已验证的域名，防止同步其他租户的账户
+func (s *scimService) checkEmailDomain(ctx context.Context, client *SCIMClient, email string) error {
+	domain, err := s.repo.GetDomain(ctx, emailDomain(email))
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return pkgErrors.WrapError(err, "查询域名失败")
+	}
+	if err != nil || domain.ProviderID != client.ProviderID || !domain.IsVerified() {
+		return pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "邮箱域名未由该身份提供方认证")
+	}
+	return nil
+}
+
+// patchSCIMUser 将一个PATCH操作应用到用户资源
+func patchSCIMUser(user *SCIMUser, op SCIMPatchOperation) error {
+	remove, err := scimPatchRemove(op)
+	if err != nil {
+		return err
+	}
+	if op.Path != "" {
+		return patchSCIMUserAttr(user, op.Path, op.Value, remove)
+	}
+	if remove {
+		return pkgErrors.WrapError(ErrSCIMInvalidPath, "remove操作必须指定path")
+	}
+	var attrs map[string]json.RawMessage
+	if err := json.Unmarshal(op.Value, &attrs); err != nil {
+		return pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "省略path时value必须是对象")
+	}
+	for path, value := range attrs {
+		if err := patchSCIMUserAttr(user, path, value, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// patchSCIMUserAttr 修改用户资源的一个属性，扩展schema和不支持的属性被忽略
+func patchSCIMUserAttr(user *SCIMUser, path string, value json.RawMessage, remove bool) error {
+	attr := strings.ToLower(path)
+	if remove {
+		switch attr {
+		case "username":
+			return pkgErrors.WrapError(ErrSCIMInvalidPath, "userName不能删除")
+		case "externalid":
+			user.ExternalID = ""
+		case "displayname":
+			user.DisplayName = ""
+		case "name":
+			user.Name = nil
+		}
+		return nil
+	}
+
+	var err error
+	switch {
+	case attr == "active":
+		var active bool
+		if active, err = parseSCIMBool(value); err == nil {
+			user.Active = &active
+		}
+	case attr == "username":
+		user.UserName, err = parseSCIMString(value, "userName")
+	case attr == "externalid":
+		user.ExternalID, err = parseSCIMString(value, "externalId")
+	case attr == "displayname":
+		user.DisplayName, err = parseSCIMString(value, "displayName")
+	case attr == "name":
+		var name SCIMName
+		if err = json.Unmarshal(value, &name); err != nil {
+			return pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "name格式错误")
+		}
+		user.Name = &name
+	case strings.HasPrefix(attr, "name."):
+		if user.Name == nil {
+			user.Name = &SCIMName{}
+		}
+		switch attr {
+		case "name.formatted":
+			user.Name.Formatted, err = parseSCIMString(value, path)
+		case "name.givenname":
+			user.Name.GivenName, err = parseSCIMString(value, path)
+		case "name.familyname":
+			user.Name.FamilyName, err = parseSCIMString(value, path)
+		}
+	case attr == "emails":
+		var emails []SCIMEmail
+		if err = json.Unmarshal(value, &emails); err != nil {
+			return pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "emails格式错误")
+		}
+		user.Emails = emails
+	case attr == "emails.value" || strings.HasPrefix(attr, "emails["):
+		// emails[type eq "work"].value 等形式只修改主邮箱
+		var email string
+		if email, err = parseSCIMString(value, path); err == nil {
+			user.Emails = []SCIMEmail{{Value: email, Type: "work", Primary: true}}
+		}
+	}
+	return err
+}
+
+// scimPatchRemove 检查操作类型，返回是否为remove操作
+func scimPatchRemove(op SCIMPatchOperation) (bool, error) {
+	switch strings.ToLower(op.Op) {
+	case "add", "replace":
+		return false, nil
+	case "remove":
+		return true, nil
+	default:
+		return false, pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "不支持的操作: %s", op.Op)
+	}
+}
+
+// normalizeSCIMUser 返回小写的userName和主邮箱，没有邮箱时使用邮箱形式的userName
+func normalizeSCIMUser(req *SCIMUser) (userName, email string, err error) {
+	userName = strings.ToLower(strings.TrimSpace(req.UserName))
+	if userName == "" {
+		return "", "", pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "userName不能为空")
+	}
+	if len(userName) > 255 {
+		return "", "", pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "userName不能超过255个字符")
+	}
+
+	for i, candidate := range req.Emails {
+		if candidate.Primary || i == 0 {
+			email = candidate.Value
+		}
+		if candidate.Primary {
+			break
+		}
+	}
+	if email == "" {
+		email = userName
+	}
+	email = strings.ToLower(strings.TrimSpace(email))
+	if emailDomain(email) == "" {
+		return "", "", pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "缺少有效的邮箱")
+	}
+	return userName, email, nil
+}
+
+// scimDisplayName 返回资源的显示名称，依次取displayName、name.formatted和姓名
+func scimDisplayName(req *SCIMUser) string {
+	if name := strings.TrimSpace(req.DisplayName); name != "" {
+		return name
+	}
+	if req.Name == nil {
+		return ""
+	}
+	if name := strings.TrimSpace(req.Name.Formatted); name != "" {
+		return name
+	}
+	return strings.TrimSpace(req.Name.GivenName + " " + req.Name.FamilyName)
+}
+
+// toSCIMUser 生成用户资源，只有正常状态的账户active为true
+func toSCIMUser(link *models.SCIMUser) *SCIMUser {
+	account := &link.User
+	active := account.Status == accountStatusActive
+	user := &SCIMUser{
+		Schemas:     []string{SCIMSchemaUser},
+		ID:          account.UUID,
+		ExternalID:  stringValue(link.ExternalID),
+		UserName:    link.UserName,
+		DisplayName: stringValue(account.DisplayName),
+		Emails:      []SCIMEmail{{Value: account.Email, Type: "work", Primary: true}},
+		Active:      &active,
+		Meta:        scimMeta("User", link.CreatedAt, link.UpdatedAt),
+	}
+	if user.DisplayName != "" {
+		user.Name = &SCIMName{Formatted: user.DisplayName}
+	}
+	return user
+}
+
+// optionalString 空字符串返回nil
+func optionalString(value string) *string {
+	if value = strings.TrimSpace(value); value == "" {
+		return nil
+	}
+	return &value
+}
+
+// stringValue 返回字符串指针的值，nil返回空
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
@@ -0,0 +1,231 @@
+// Package sso 企业单点登录
+//
+// 企业租户配置自己的OIDC身份提供方，并通过DNS TXT记录认领邮箱域名。
+// 已验证域名的用户按邮箱跳转到身份提供方登录，首次登录时即时开通账户，
+// ID令牌中的组按配置映射为角色；开启强制SSO后该域名的用户不能使用密码登录。
+// 身份提供方还可以通过SCIM 2.0接口同步用户和组，组成员同步为团队成员。
+// 自托管部署可以启用LDAP，密码登录先在企业目录中验证，所属组映射为角色和团队。
+// SAML暂不支持，身份提供方需提供OIDC接口
+package sso
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/cache"
+	"cloudpan/internal/pkg/config"
+	"cloudpan/internal/pkg/oidc"
+	"cloudpan/internal/repository/models"
+	userrepo "cloudpan/internal/repository/user"
+)
+
+// 默认参数
+const (
+	DefaultStateTTL     = 10 * time.Minute
+	DefaultStorageQuota = int64(10 << 30) // 即时开通账户的默认存储配额(10GB)，与注册一致
+
+	// providerCacheTTL 发现文档和签名公钥的缓存时间，修改签发者地址后立即失效
+	providerCacheTTL = time.Hour
+)
+
+var (
+	// ErrLoginFailed 单点登录回调失败(请求已失效、身份提供方拒绝或账户无法关联)
+	ErrLoginFailed = errors.New("sso login failed")
+	// ErrSSORequired 邮箱域名已开启强制SSO，不能使用密码登录
+	ErrSSORequired = errors.New("sso login required")
+)
+
+// UserStore 单点登录需要的用户数据访问，由 userrepo.UserRepository 实现
+type UserStore interface {
+	Create(ctx context.Context, user *models.User) error
+	GetByID(ctx context.Context, id uint) (*models.User, error)
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	ExistsByUsername(ctx context.Context, username string) (bool, error)
+}
+
+// TXTResolver 查询域名TXT记录，由 net.Resolver 实现
+type TXTResolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// Options 单点登录选项
+type Options struct {
+	RedirectURL  string        // 前端回调页面地址
+	StateTTL     time.Duration // 登录请求有效期
+	HTTPTimeout  time.Duration // 访问身份提供方的超时
+	StorageQuota int64         // 即时开通账户的存储配额
+}
+
+// OptionsFromConfig 从单点登录配置生成选项
+func OptionsFromConfig(cfg config.SSOConfig) Options {
+	return Options{
+		RedirectURL: cfg.RedirectURL,
+		StateTTL:    cfg.StateTTL,
+		HTTPTimeout: cfg.HTTPTimeout,
+	}
+}
+
+// ProviderRequest 创建或更新身份提供方的请求
+type ProviderRequest struct {
+	Tenant       string            `json:"tenant" example:"acme"`                                      // 租户标识，只在创建时使用
+	Name         string            `json:"name" binding:"required" example:"Acme SSO"`                 // 显示名称
+	Issuer       string            `json:"issuer" binding:"required" example:"https://login.acme.com"` // 签发者地址
+	ClientID     string            `json:"client_id" binding:"required" example:"cloudpan"`            // 客户端ID
+	ClientSecret *string           `json:"client_secret,omitempty"`                                    // 客户端密钥，更新时省略表示不修改
+	Scopes       string            `json:"scopes" example:"email profile"`                             // 额外申请的scope(空格分隔)
+	GroupsClaim  string            `json:"groups_claim" example:"groups"`                              // 组列表的声明名，默认groups
+	RoleMappings map[string]string `json:"role_mappings,omitempty"`                                    // 组到角色(user/moderator/admin)的映射
+	DefaultRole  string            `json:"default_role" example:"user"`                                // 没有组匹配时的角色，默认user
+	JITEnabled   *bool             `json:"jit_enabled,omitempty"`                                      // 是否即时开通账户，默认开启
+	EnforceSSO   *bool             `json:"enforce_sso,omitempty"`                                      // 是否禁止已验证域名的用户使用密码登录，默认关闭
+	IsActive     *bool             `json:"is_active,omitempty"`                                        // 是否启用，默认启用
+}
+
+// DomainInfo 邮箱域名登记信息，包含验证需要添加的DNS TXT记录
+type DomainInfo struct {
+	*models.SSODomain
+	RecordName  string `json:"record_name" example:"_cloudpan-sso.acme.com"`                    // TXT记录名
+	RecordValue string `json:"record_value" example:"cloudpan-sso-verification=3b5d5c37129550"` // TXT记录值
+}
+
+// Discovery 按邮箱查询单点登录的结果
+type Discovery struct {
+	SSO          bool   `json:"sso"`                     // 邮箱域名是否配置了单点登录
+	Tenant       string `json:"tenant,omitempty"`        // 租户标识
+	ProviderName string `json:"provider_name,omitempty"` // 身份提供方名称
+	Enforced     bool   `json:"enforced"`                // 是否禁止密码登录
+}
+
+// AuthorizeRequest 发起单点登录的请求，邮箱和租户二选一
+type AuthorizeRequest struct {
+	Email      string `json:"email,omitempty" example:"alice@acme.com"` // 用户邮箱，按域名查找身份提供方
+	Tenant     string `json:"tenant,omitempty" example:"acme"`          // 租户标识
+	RememberMe bool   `json:"remember_me,omitempty"`                    // 记住我
+}
+
+// Authorization 跳转到身份提供方的授权地址
+type Authorization struct {
+	AuthorizationURL string    `json:"authorization_url"` // 浏览器跳转地址
+	State            string    `json:"state"`             // 回调时携带的state
+	ExpiresAt        time.Time `json:"expires_at"`        // 登录请求过期时间
+}
+
+// LoginResult 单点登录回调的结果
+type LoginResult struct {
+	User        *models.User // 登录的本地账户
+	Role        string       // 身份提供方映射的角色
+	RememberMe  bool         // 发起登录时的记住我选项
+	Provisioned bool         // 是否为本次登录即时开通的账户
+	Tenant      string       // 租户标识
+}
+
+// Service 企业单点登录服务接口
+//
+// 登录流程：
+// 1. Discover 按邮箱查询是否需要跳转到身份提供方
+// 2. Authorize 生成带state、nonce和PKCE的授权地址，前端跳转
+// 3. Complete 前端回调页面提交state和授权码，换取并验证ID令牌后返回要登录的账户
+//
+// 使用示例：
+//
+//	service := sso.NewService(userrepo.NewSSORepository(db), userrepo.NewUserRepository(db), cache.DefaultSSOStateStore(), net.DefaultResolver, sso.OptionsFromConfig(cfg), logger)
+//	auth, err := service.Authorize(ctx, &sso.AuthorizeRequest{Email: "alice@acme.com"})
+//	result, err := service.Complete(ctx, state, code)
+type Service interface {
+	// 身份提供方管理
+	ListProviders(ctx context.Context) ([]*models.SSOProvider, error)
+	GetProvider(ctx context.Context, id uint) (*models.SSOProvider, error)
+	CreateProvider(ctx context.Context, req *ProviderRequest) (*models.SSOProvider, error)
+	UpdateProvider(ctx context.Context, id uint, req *ProviderRequest) (*models.SSOProvider, error)
+	DeleteProvider(ctx context.Context, id uint) error
+
+	// 邮箱域名认领
+	AddDomain(ctx context.Context, providerID uint, domain string) (*DomainInfo, error)
+	VerifyDomain(ctx context.Context, providerID, domainID uint) (*DomainInfo, error)
+	DeleteDomain(ctx context.Context, providerID, domainID uint) error
+
+	// 登录
+	Discover(ctx context.Context, email string) (*Discovery, error)
+	Authorize(ctx context.Context, req *AuthorizeRequest) (*Authorization, error)
+	Complete(ctx context.Context, state, code string) (*LoginResult, error)
+	CheckPasswordLogin(ctx context.Context, email string) (*Discovery, error)
+}
+
+// service 企业单点登录服务实现
+type service struct {
+	repo     userrepo.SSORepository
+	users    UserStore
+	states   cache.SSOStateStore
+	resolver TXTResolver
+	client   *http.Client
+	options  Options
+	logger   *zap.Logger
+	now      func() time.Time
+
+	mu        sync.Mutex
+	providers map[uint]*cachedProvider
+}
+
+// cachedProvider 已完成发现的身份提供方
+type cachedProvider struct {
+	issuer    string
+	provider  *oidc.Provider
+	fetchedAt time.Time
+}
+
+// NewService 创建企业单点登录服务
+//
+// resolver 可以为nil，此时使用 net.DefaultResolver 验证域名
+func NewService(repo userrepo.SSORepository, users UserStore, states cache.SSOStateStore, resolver TXTResolver, options Options, logger *zap.Logger) Service {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	if options.StateTTL <= 0 {
+		options.StateTTL = DefaultStateTTL
+	}
+	if options.HTTPTimeout <= 0 {
+		options.HTTPTimeout = oidc.DefaultHTTPTimeout
+	}
+	if options.StorageQuota <= 0 {
+		options.StorageQuota = DefaultStorageQuota
+	}
+	return &service{
+		repo:      repo,
+		users:     users,
+		states:    states,
+		resolver:  resolver,
+		client:    &http.Client{Timeout: options.HTTPTimeout},
+		options:   options,
+		logger:    logger,
+		now:       time.Now,
+		providers: make(map[uint]*cachedProvider),
+	}
+}
+
+var (
+	defaultServiceMu sync.RWMutex
+	defaultService   Service
+)
+
+// SetDefault 设置全局单点登录服务，启动时调用
+func SetDefault(service Service) {
+	defaultServiceMu.Lock()
+	defer defaultServiceMu.Unlock()
+	defaultService = service
+}
+
+// Default 返回全局单点登录服务，未启用单点登录时返回nil
+func Default() Service {
+	defaultServiceMu.RLock()
+	defer defaultServiceMu.RUnlock()
+	return defaultService
+}
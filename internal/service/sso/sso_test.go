@@ -0,0 +1,523 @@
+package sso
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/cache"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/repository/models"
+)
+
+// memorySSORepository 内存实现的单点登录数据仓库
+type memorySSORepository struct {
+	providers  map[uint]*models.SSOProvider
+	domains    map[uint]*models.SSODomain
+	identities map[string]*models.SSOIdentity
+	nextID     uint
+}
+
+func newMemorySSORepository() *memorySSORepository {
+	return &memorySSORepository{
+		providers:  make(map[uint]*models.SSOProvider),
+		domains:    make(map[uint]*models.SSODomain),
+		identities: make(map[string]*models.SSOIdentity),
+	}
+}
+
+func (r *memorySSORepository) id() uint {
+	r.nextID++
+	return r.nextID
+}
+
+func (r *memorySSORepository) CreateProvider(_ context.Context, provider *models.SSOProvider) error {
+	provider.ID = r.id()
+	copied := *provider
+	r.providers[provider.ID] = &copied
+	return nil
+}
+
+func (r *memorySSORepository) UpdateProvider(_ context.Context, provider *models.SSOProvider) error {
+	copied := *provider
+	r.providers[provider.ID] = &copied
+	return nil
+}
+
+func (r *memorySSORepository) DeleteProvider(_ context.Context, id uint) error {
+	delete(r.providers, id)
+	for domainID, domain := range r.domains {
+		if domain.ProviderID == id {
+			delete(r.domains, domainID)
+		}
+	}
+	return nil
+}
+
+func (r *memorySSORepository) GetProvider(_ context.Context, id uint) (*models.SSOProvider, error) {
+	provider, ok := r.providers[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *provider
+	return &copied, nil
+}
+
+func (r *memorySSORepository) GetProviderByTenant(ctx context.Context, tenant string) (*models.SSOProvider, error) {
+	for id, provider := range r.providers {
+		if provider.Tenant == tenant {
+			return r.GetProvider(ctx, id)
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memorySSORepository) ListProviders(_ context.Context) ([]*models.SSOProvider, error) {
+	providers := make([]*models.SSOProvider, 0, len(r.providers))
+	for _, provider := range r.providers {
+		providers = append(providers, provider)
+	}
+	return providers, nil
+}
+
+func (r *memorySSORepository) CreateDomain(_ context.Context, domain *models.SSODomain) error {
+	domain.ID = r.id()
+	copied := *domain
+	r.domains[domain.ID] = &copied
+	return nil
+}
+
+func (r *memorySSORepository) GetDomain(_ context.Context, name string) (*models.SSODomain, error) {
+	for _, domain := range r.domains {
+		if domain.Domain == name {
+			copied := *domain
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memorySSORepository) GetDomainByID(_ context.Context, id uint) (*models.SSODomain, error) {
+	domain, ok := r.domains[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *domain
+	return &copied, nil
+}
+
+func (r *memorySSORepository) MarkDomainVerified(_ context.Context, id uint, verifiedAt time.Time) error {
+	r.domains[id].VerifiedAt = &verifiedAt
+	return nil
+}
+
+func (r *memorySSORepository) DeleteDomain(_ context.Context, id uint) error {
+	delete(r.domains, id)
+	return nil
+}
+
+func (r *memorySSORepository) GetIdentity(_ context.Context, providerID uint, subject string) (*models.SSOIdentity, error) {
+	for _, identity := range r.identities {
+		if identity.ProviderID == providerID && identity.Subject == subject {
+			copied := *identity
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memorySSORepository) SaveIdentity(_ context.Context, identity *models.SSOIdentity) error {
+	key := identity.Subject
+	if existing, ok := r.identities[key]; ok && existing.ProviderID == identity.ProviderID {
+		existing.Email = identity.Email
+		existing.Role = identity.Role
+		existing.LastLoginAt = identity.LastLoginAt
+		return nil
+	}
+	copied := *identity
+	r.identities[key] = &copied
+	return nil
+}
+
+// memoryUsers 内存实现的用户数据访问
+type memoryUsers struct {
+	users  map[uint]*models.User
+	nextID uint
+}
+
+func (u *memoryUsers) Create(_ context.Context, user *models.User) error {
+	u.nextID++
+	user.ID = u.nextID
+	if user.UUID == "" {
+		user.UUID = fmt.Sprintf("user-%d", user.ID)
+	}
+	u.users[user.ID] = user
+	return nil
+}
+
+func (u *memoryUsers) Update(_ context.Context, user *models.User) error {
+	copied := *user
+	u.users[user.ID] = &copied
+	return nil
+}
+
+func (u *memoryUsers) GetByID(_ context.Context, id uint) (*models.User, error) {
+	if user, ok := u.users[id]; ok {
+		return user, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (u *memoryUsers) GetByEmail(_ context.Context, email string) (*models.User, error) {
+	for _, user := range u.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (u *memoryUsers) ExistsByUsername(_ context.Context, username string) (bool, error) {
+	for _, user := range u.users {
+		if user.Username == username {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// staticResolver 返回固定TXT记录的解析器
+type staticResolver map[string][]string
+
+func (r staticResolver) LookupTXT(_ context.Context, name string) ([]string, error) {
+	if values, ok := r[name]; ok {
+		return values, nil
+	}
+	return nil, errors.New("no such host")
+}
+
+// testIdP 签发ID令牌的OIDC身份提供方，令牌中的nonce取自最近一次授权地址
+type testIdP struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	nonce  string
+	claims jwt.MapClaims
+}
+
+func newTestIdP(t *testing.T) *testIdP {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	idp := &testIdP{key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.server.URL,
+			"authorization_endpoint": idp.server.URL + "/authorize",
+			"token_endpoint":         idp.server.URL + "/token",
+			"jwks_uri":               idp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "k1",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("code") != "good-code" || r.FormValue("code_verifier") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+		claims := jwt.MapClaims{
+			"iss":   idp.server.URL,
+			"aud":   "cloudpan",
+			"nonce": idp.nonce,
+			"iat":   time.Now().Unix(),
+			"exp":   time.Now().Add(time.Hour).Unix(),
+		}
+		for k, v := range idp.claims {
+			claims[k] = v
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = "k1"
+		signed, err := token.SignedString(key)
+		require.NoError(t, err)
+		_ = json.NewEncoder(w).Encode(map[string]string{"access_token": "at", "token_type": "Bearer", "id_token": signed})
+	})
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+type ssoFixture struct {
+	service  *service
+	repo     *memorySSORepository
+	users    *memoryUsers
+	idp      *testIdP
+	resolver staticResolver
+}
+
+func newSSOFixture(t *testing.T) *ssoFixture {
+	t.Helper()
+	repo := newMemorySSORepository()
+	users := &memoryUsers{users: make(map[uint]*models.User)}
+	resolver := staticResolver{}
+	svc := NewService(repo, users, cache.NewMemorySSOStateStore(), resolver,
+		Options{RedirectURL: "https://app.example.com/sso/callback"}, zap.NewNop()).(*service)
+	return &ssoFixture{service: svc, repo: repo, users: users, idp: newTestIdP(t), resolver: resolver}
+}
+
+// setupProvider 创建身份提供方并认领、验证 acme.com
+func (f *ssoFixture) setupProvider(t *testing.T, req *ProviderRequest) *models.SSOProvider {
+	t.Helper()
+	ctx := context.Background()
+	if req == nil {
+		req = &ProviderRequest{}
+	}
+	req.Tenant = "acme"
+	req.Name = "Acme"
+	req.Issuer = f.idp.server.URL
+	req.ClientID = "cloudpan"
+	provider, err := f.service.CreateProvider(ctx, req)
+	require.NoError(t, err)
+
+	domain, err := f.service.AddDomain(ctx, provider.ID, "ACME.com")
+	require.NoError(t, err)
+	assert.Equal(t, "acme.com", domain.Domain)
+	assert.Equal(t, "_cloudpan-sso.acme.com", domain.RecordName)
+	f.resolver[domain.RecordName] = []string{"v=spf1 -all", domain.RecordValue}
+	_, err = f.service.VerifyDomain(ctx, provider.ID, domain.ID)
+	require.NoError(t, err)
+	return provider
+}
+
+// login 走完授权和回调，返回回调结果
+func (f *ssoFixture) login(t *testing.T, claims jwt.MapClaims) (*LoginResult, error) {
+	t.Helper()
+	ctx := context.Background()
+	auth, err := f.service.Authorize(ctx, &AuthorizeRequest{Email: "alice@acme.com", RememberMe: true})
+	require.NoError(t, err)
+	authURL, err := url.Parse(auth.AuthorizationURL)
+	require.NoError(t, err)
+	assert.Equal(t, auth.State, authURL.Query().Get("state"))
+	assert.Equal(t, "S256", authURL.Query().Get("code_challenge_method"))
+
+	f.idp.nonce = authURL.Query().Get("nonce")
+	f.idp.claims = claims
+	return f.service.Complete(ctx, auth.State, "good-code")
+}
+
+func TestService_ProviderValidation(t *testing.T) {
+	ctx := context.Background()
+	f := newSSOFixture(t)
+	base := func() *ProviderRequest {
+		return &ProviderRequest{Tenant: "acme", Name: "Acme", Issuer: f.idp.server.URL, ClientID: "cloudpan"}
+	}
+
+	cases := map[string]func(*ProviderRequest){
+		"invalid tenant":     func(r *ProviderRequest) { r.Tenant = "Acme Corp" },
+		"plain http issuer":  func(r *ProviderRequest) { r.Issuer = "http://login.acme.com" },
+		"superuser mapping":  func(r *ProviderRequest) { r.RoleMappings = map[string]string{"root": "superuser"} },
+		"unknown default":    func(r *ProviderRequest) { r.DefaultRole = "owner" },
+		"issuer unreachable": func(r *ProviderRequest) { r.Issuer = f.idp.server.URL + "/missing" },
+		"client id missing":  func(r *ProviderRequest) { r.ClientID = " " },
+	}
+	for name, mutate := range cases {
+		t.Run(name, func(t *testing.T) {
+			req := base()
+			mutate(req)
+			_, err := f.service.CreateProvider(ctx, req)
+			assert.True(t, pkgErrors.IsValidationError(err), "got %v", err)
+		})
+	}
+
+	provider, err := f.service.CreateProvider(ctx, base())
+	require.NoError(t, err)
+	assert.True(t, provider.JITEnabled)
+	assert.True(t, provider.IsActive)
+	assert.Equal(t, "groups", provider.GroupsClaim)
+
+	_, err = f.service.CreateProvider(ctx, base())
+	assert.ErrorIs(t, err, pkgErrors.ErrResourceExists)
+
+	update := base()
+	update.Tenant = "other"
+	_, err = f.service.UpdateProvider(ctx, provider.ID, update)
+	assert.ErrorIs(t, err, pkgErrors.ErrOperationNotAllowed)
+}
+
+func TestService_DomainVerification(t *testing.T) {
+	ctx := context.Background()
+	f := newSSOFixture(t)
+	provider, err := f.service.CreateProvider(ctx, &ProviderRequest{Tenant: "acme", Name: "Acme", Issuer: f.idp.server.URL, ClientID: "cloudpan"})
+	require.NoError(t, err)
+
+	_, err = f.service.AddDomain(ctx, provider.ID, "not a domain")
+	assert.True(t, pkgErrors.IsValidationError(err))
+
+	domain, err := f.service.AddDomain(ctx, provider.ID, "acme.com")
+	require.NoError(t, err)
+	_, err = f.service.AddDomain(ctx, provider.ID, "acme.com")
+	assert.ErrorIs(t, err, pkgErrors.ErrResourceExists)
+
+	// 没有TXT记录或值不正确时不通过
+	_, err = f.service.VerifyDomain(ctx, provider.ID, domain.ID)
+	assert.True(t, pkgErrors.IsValidationError(err))
+	f.resolver[domain.RecordName] = []string{"cloudpan-sso-verification=wrong"}
+	_, err = f.service.VerifyDomain(ctx, provider.ID, domain.ID)
+	assert.True(t, pkgErrors.IsValidationError(err))
+
+	// 未验证的域名不触发单点登录
+	discovery, err := f.service.Discover(ctx, "alice@acme.com")
+	require.NoError(t, err)
+	assert.False(t, discovery.SSO)
+
+	f.resolver[domain.RecordName] = []string{domain.RecordValue}
+	verified, err := f.service.VerifyDomain(ctx, provider.ID, domain.ID)
+	require.NoError(t, err)
+	assert.True(t, verified.IsVerified())
+
+	discovery, err = f.service.Discover(ctx, "Alice@ACME.com")
+	require.NoError(t, err)
+	assert.True(t, discovery.SSO)
+	assert.Equal(t, "acme", discovery.Tenant)
+
+	// 其他身份提供方的域名ID不可操作
+	_, err = f.service.VerifyDomain(ctx, provider.ID+100, domain.ID)
+	assert.True(t, pkgErrors.IsNotFoundError(err))
+}
+
+func TestService_LoginProvisionsAndMapsRoles(t *testing.T) {
+	f := newSSOFixture(t)
+	f.setupProvider(t, &ProviderRequest{RoleMappings: map[string]string{"staff": "moderator", "it-admins": "admin"}})
+
+	claims := jwt.MapClaims{"sub": "okta|42", "email": "Alice@acme.com", "email_verified": true, "name": "Alice", "groups": []string{"staff", "it-admins"}}
+	result, err := f.login(t, claims)
+	require.NoError(t, err)
+	assert.True(t, result.Provisioned)
+	assert.True(t, result.RememberMe)
+	assert.Equal(t, "admin", result.Role)
+	assert.Equal(t, "alice@acme.com", result.User.Email)
+	assert.Equal(t, "alice", result.User.Username)
+	assert.True(t, result.User.EmailVerified)
+	assert.Equal(t, "acme", (*result.User.Profile)[profileKeyTenant])
+	assert.NotEmpty(t, result.User.PasswordHash)
+
+	// 再次登录按subject关联同一账户，组变化后角色随之变化
+	claims["groups"] = []string{"staff"}
+	claims["email"] = "alice.renamed@acme.com"
+	again, err := f.login(t, claims)
+	require.NoError(t, err)
+	assert.False(t, again.Provisioned)
+	assert.Equal(t, result.User.ID, again.User.ID)
+	assert.Equal(t, "moderator", again.Role)
+	assert.Len(t, f.users.users, 1)
+}
+
+func TestService_LoginRejects(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("unverified email", func(t *testing.T) {
+		f := newSSOFixture(t)
+		f.setupProvider(t, nil)
+		_, err := f.login(t, jwt.MapClaims{"sub": "1", "email": "alice@acme.com", "email_verified": false})
+		assert.ErrorIs(t, err, ErrLoginFailed)
+		assert.Empty(t, f.users.users)
+	})
+
+	t.Run("email outside claimed domain", func(t *testing.T) {
+		f := newSSOFixture(t)
+		f.setupProvider(t, nil)
+		_, err := f.login(t, jwt.MapClaims{"sub": "1", "email": "ceo@victim.com", "email_verified": true})
+		assert.ErrorIs(t, err, ErrLoginFailed)
+	})
+
+	t.Run("jit disabled", func(t *testing.T) {
+		f := newSSOFixture(t)
+		disabled := false
+		f.setupProvider(t, &ProviderRequest{JITEnabled: &disabled})
+		_, err := f.login(t, jwt.MapClaims{"sub": "1", "email": "alice@acme.com", "email_verified": true})
+		assert.ErrorIs(t, err, ErrLoginFailed)
+
+		// 已有同邮箱账户时直接关联
+		existing := &models.User{Email: "alice@acme.com", Username: "alice"}
+		require.NoError(t, f.users.Create(ctx, existing))
+		result, err := f.login(t, jwt.MapClaims{"sub": "1", "email": "alice@acme.com", "email_verified": true})
+		require.NoError(t, err)
+		assert.Equal(t, existing.ID, result.User.ID)
+		assert.Equal(t, "user", result.Role)
+	})
+
+	t.Run("state replay", func(t *testing.T) {
+		f := newSSOFixture(t)
+		f.setupProvider(t, nil)
+		auth, err := f.service.Authorize(ctx, &AuthorizeRequest{Tenant: "acme"})
+		require.NoError(t, err)
+		authURL, _ := url.Parse(auth.AuthorizationURL)
+		f.idp.nonce = authURL.Query().Get("nonce")
+		f.idp.claims = jwt.MapClaims{"sub": "1", "email": "alice@acme.com", "email_verified": true}
+
+		_, err = f.service.Complete(ctx, auth.State, "good-code")
+		require.NoError(t, err)
+		_, err = f.service.Complete(ctx, auth.State, "good-code")
+		assert.ErrorIs(t, err, ErrLoginFailed)
+	})
+
+	t.Run("code rejected", func(t *testing.T) {
+		f := newSSOFixture(t)
+		f.setupProvider(t, nil)
+		auth, err := f.service.Authorize(ctx, &AuthorizeRequest{Tenant: "acme"})
+		require.NoError(t, err)
+		_, err = f.service.Complete(ctx, auth.State, "bad-code")
+		assert.ErrorIs(t, err, ErrLoginFailed)
+	})
+}
+
+func TestService_CheckPasswordLogin(t *testing.T) {
+	ctx := context.Background()
+	f := newSSOFixture(t)
+	enforce := true
+	provider := f.setupProvider(t, &ProviderRequest{EnforceSSO: &enforce})
+
+	discovery, err := f.service.CheckPasswordLogin(ctx, "bob@acme.com")
+	assert.ErrorIs(t, err, ErrSSORequired)
+	assert.Equal(t, "acme", discovery.Tenant)
+
+	_, err = f.service.CheckPasswordLogin(ctx, "bob@other.com")
+	assert.NoError(t, err)
+
+	// 停用身份提供方后恢复密码登录
+	inactive := false
+	_, err = f.service.UpdateProvider(ctx, provider.ID, &ProviderRequest{Name: "Acme", Issuer: f.idp.server.URL, ClientID: "cloudpan", IsActive: &inactive})
+	require.NoError(t, err)
+	_, err = f.service.CheckPasswordLogin(ctx, "bob@acme.com")
+	assert.NoError(t, err)
+}
+
+func TestUsernameFromEmail(t *testing.T) {
+	assert.Equal(t, "john-doe", usernameFromEmail("john.doe@acme.com"))
+	assert.Equal(t, "u007", usernameFromEmail("007@acme.com"))
+	assert.Equal(t, "u", usernameFromEmail("..@acme.com"))
+	assert.Equal(t, "zhang", usernameFromEmail("zhang+张三@acme.com"))
+}
@@ -0,0 +1,346 @@
+package team
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/cache"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/repository/models"
+)
+
+// cachedMembership 缓存的成员角色，Role为空表示不是团队的活跃或归档成员
+type cachedMembership struct {
+	Role     string `json:"role"`
+	Archived bool   `json:"archived,omitempty"` // 团队已删除，成员只能查看
+}
+
+// ListMembers 列出团队成员，团队的任何成员都可以查看
+func (s *teamService) ListMembers(ctx context.Context, userID, teamID uint) ([]*MemberInfo, error) {
+	if _, err := s.authorize(ctx, teamID, userID); err != nil {
+		return nil, err
+	}
+
+	members, err := s.teams.ListMembers(ctx, teamID)
+	if err != nil {
+		return nil, fmt.Errorf("获取团队成员失败: %w", err)
+	}
+	infos := make([]*MemberInfo, 0, len(members))
+	for _, member := range members {
+		infos = append(infos, toMemberInfo(member, &member.User))
+	}
+	return infos, nil
+}
+
+// InviteMember 按邮箱或用户名邀请已注册用户加入团队，被邀请的用户直接成为成员
+//
+// 所有者和管理员可以邀请成员，只有所有者可以直接邀请管理员
+func (s *teamService) InviteMember(ctx context.Context, userID, teamID uint, req *InviteMemberRequest) (*MemberInfo, error) {
+	if req == nil || strings.TrimSpace(req.User) == "" {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "被邀请用户不能为空")
+	}
+	actor, err := s.authorizeWrite(ctx, teamID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !actor.CanManageMembers() {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, "只有团队所有者和管理员可以邀请成员")
+	}
+
+	role := req.Role
+	if role == "" {
+		role = models.TeamRoleMember
+	}
+	if !isAssignableRole(role) {
+		return nil, pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "不支持的成员角色 %q", role)
+	}
+	if role == models.TeamRoleAdmin && !actor.IsOwner() {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, "只有团队所有者可以任命管理员")
+	}
+
+	user, err := s.findUser(ctx, strings.TrimSpace(req.User))
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	member := &models.TeamMember{
+		TeamID:    teamID,
+		UserID:    user.ID,
+		Role:      role,
+		Status:    models.TeamMemberStatusActive,
+		JoinedAt:  &now,
+		InvitedBy: &userID,
+	}
+	var checkErr error
+	err = s.teams.AddMember(ctx, member, func(team *models.Team) error {
+		switch {
+		case !team.IsActive():
+			checkErr = pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "团队已停用，不能邀请成员")
+		case !team.CanAddMember():
+			checkErr = pkgErrors.WrapErrorf(pkgErrors.ErrQuotaExceeded, "团队成员已达上限 %d 人", team.MaxMembers)
+		}
+		return checkErr
+	})
+	switch {
+	case err == nil:
+	case checkErr != nil && errors.Is(err, checkErr):
+		return nil, checkErr
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "团队不存在")
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return nil, pkgErrors.WrapError(pkgErrors.ErrResourceExists, "该用户已是团队成员")
+	default:
+		return nil, fmt.Errorf("添加团队成员失败: %w", err)
+	}
+	s.forgetMembers(teamID, user.ID)
+
+	s.logger.Info("Team member added",
+		zap.Uint("team_id", teamID),
+		zap.Uint("user_id", user.ID),
+		zap.Uint("invited_by", userID),
+		zap.String("role", role))
+	return toMemberInfo(member, user), nil
+}
+
+// UpdateMemberRole 修改成员角色
+//
+// 只有所有者可以任命或撤销管理员；新角色为owner时表示转让所有权，原所有者成为管理员。
+// 所有者的角色只能通过转让所有权改变
+func (s *teamService) UpdateMemberRole(ctx context.Context, userID, teamID, memberID uint, role string) (*MemberInfo, error) {
+	actor, err := s.authorizeWrite(ctx, teamID, userID)
+	if err != nil {
+		return nil, err
+	}
+	target, err := s.getMember(ctx, teamID, memberID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case role == models.TeamRoleOwner:
+		if !actor.IsOwner() {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, "只有团队所有者可以转让所有权")
+		}
+		if target.IsOwner() {
+			break
+		}
+		if !target.IsActive() {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "只能将团队转让给活跃成员")
+		}
+		if err := s.teams.TransferOwnership(ctx, teamID, userID, memberID); err != nil {
+			return nil, fmt.Errorf("转让团队失败: %w", err)
+		}
+		s.forgetMembers(teamID, userID, memberID)
+		s.logger.Info("Team ownership transferred",
+			zap.Uint("team_id", teamID),
+			zap.Uint("from_user_id", userID),
+			zap.Uint("to_user_id", memberID))
+	case !isAssignableRole(role):
+		return nil, pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "不支持的成员角色 %q", role)
+	case target.IsOwner():
+		return nil, pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "不能修改团队所有者的角色，请先转让所有权")
+	case !actor.CanManageMembers():
+		return nil, pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, "只有团队所有者和管理员可以修改成员角色")
+	case !actor.IsOwner() && (target.Role == models.TeamRoleAdmin || role == models.TeamRoleAdmin):
+		return nil, pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, "只有团队所有者可以任命或撤销管理员")
+	default:
+		if err := s.teams.UpdateMemberRole(ctx, teamID, memberID, role); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "成员不存在")
+			}
+			return nil, fmt.Errorf("修改成员角色失败: %w", err)
+		}
+		s.forgetMembers(teamID, memberID)
+		s.logger.Info("Team member role changed",
+			zap.Uint("team_id", teamID),
+			zap.Uint("user_id", memberID),
+			zap.Uint("changed_by", userID),
+			zap.String("role", role))
+	}
+	target.Role = role
+
+	user, err := s.users.GetByID(ctx, memberID)
+	if err != nil {
+		return nil, fmt.Errorf("获取成员账户失败: %w", err)
+	}
+	return toMemberInfo(target, user), nil
+}
+
+// RemoveMember 将成员移出团队，memberID 为当前用户时表示离开团队
+//
+// 所有者不能离开团队；管理员只能移除普通成员和只读成员
+func (s *teamService) RemoveMember(ctx context.Context, userID, teamID, memberID uint) error {
+	actor, err := s.authorizeWrite(ctx, teamID, userID)
+	if err != nil {
+		return err
+	}
+	target, err := s.getMember(ctx, teamID, memberID)
+	if err != nil {
+		return err
+	}
+
+	if target.IsOwner() {
+		return pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "团队所有者不能离开团队，请先转让所有权或删除团队")
+	}
+	if memberID != userID {
+		if !actor.CanManageMembers() {
+			return pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, "只有团队所有者和管理员可以移除成员")
+		}
+		if target.Role == models.TeamRoleAdmin && !actor.IsOwner() {
+			return pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, "只有团队所有者可以移除管理员")
+		}
+	}
+
+	if err := s.teams.RemoveMember(ctx, teamID, memberID); err != nil {
+		return fmt.Errorf("移除团队成员失败: %w", err)
+	}
+	s.forgetMembers(teamID, memberID)
+
+	s.logger.Info("Team member removed",
+		zap.Uint("team_id", teamID),
+		zap.Uint("user_id", memberID),
+		zap.Uint("removed_by", userID))
+	return nil
+}
+
+// authorize 获取用户在团队中的成员身份，不是活跃或归档成员时按团队不存在处理
+//
+// 团队已删除时返回状态为archived的成员身份，只能用于查看
+func (s *teamService) authorize(ctx context.Context, teamID, userID uint) (*models.TeamMember, error) {
+	if userID == 0 || teamID == 0 {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "用户ID和团队ID不能为空")
+	}
+	membership, err := s.membership(ctx, teamID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if membership.Role == "" {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "团队不存在")
+	}
+	status := models.TeamMemberStatusActive
+	if membership.Archived {
+		status = models.TeamMemberStatusArchived
+	}
+	return &models.TeamMember{TeamID: teamID, UserID: userID, Role: membership.Role, Status: status}, nil
+}
+
+// authorizeWrite 获取用户在团队中的成员身份用于修改团队，团队已删除时不允许修改
+func (s *teamService) authorizeWrite(ctx context.Context, teamID, userID uint) (*models.TeamMember, error) {
+	actor, err := s.authorize(ctx, teamID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if actor.Status == models.TeamMemberStatusArchived {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "团队已删除，恢复前只能查看")
+	}
+	return actor, nil
+}
+
+// membership 查询用户在团队中的角色，不是活跃或归档成员时Role为空
+//
+// 结果(包括不是成员)缓存在Redis中，缓存读写失败时直接查询数据库
+func (s *teamService) membership(ctx context.Context, teamID, userID uint) (*cachedMembership, error) {
+	key := s.membershipKey(teamID, userID)
+	if s.cache != nil {
+		var cached cachedMembership
+		err := s.cache.Get(key, &cached)
+		if err == nil {
+			return &cached, nil
+		}
+		if !errors.Is(err, cache.ErrCacheNotFound) {
+			s.logger.Warn("读取团队成员缓存失败", zap.Uint("team_id", teamID), zap.Uint("user_id", userID), zap.Error(err))
+		}
+	}
+
+	membership := &cachedMembership{}
+	member, err := s.teams.GetMember(ctx, teamID, userID)
+	switch {
+	case err == nil:
+		switch member.Status {
+		case models.TeamMemberStatusActive:
+			membership.Role = member.Role
+		case models.TeamMemberStatusArchived:
+			membership.Role = member.Role
+			membership.Archived = true
+		}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+	default:
+		return nil, fmt.Errorf("查询团队成员失败: %w", err)
+	}
+
+	if s.cache != nil {
+		if err := s.cache.SetWithTTL(key, membership, s.ttl); err != nil {
+			s.logger.Warn("缓存团队成员失败", zap.Uint("team_id", teamID), zap.Uint("user_id", userID), zap.Error(err))
+		}
+	}
+	return membership, nil
+}
+
+// forgetMembers 清除成员角色缓存，成员加入、移除、角色变更或团队删除、恢复后调用
+func (s *teamService) forgetMembers(teamID uint, userIDs ...uint) {
+	if s.cache == nil || len(userIDs) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(userIDs))
+	for _, userID := range userIDs {
+		keys = append(keys, s.membershipKey(teamID, userID))
+	}
+	if err := s.cache.Delete(keys...); err != nil {
+		s.logger.Warn("清除团队成员缓存失败", zap.Uint("team_id", teamID), zap.Error(err))
+	}
+}
+
+// membershipKey 成员角色缓存键
+func (s *teamService) membershipKey(teamID, userID uint) string {
+	return s.keys.TeamPermissions(strconv.FormatUint(uint64(teamID), 10), strconv.FormatUint(uint64(userID), 10))
+}
+
+// getMember 获取团队成员记录，不存在时返回资源不存在错误
+func (s *teamService) getMember(ctx context.Context, teamID, userID uint) (*models.TeamMember, error) {
+	member, err := s.teams.GetMember(ctx, teamID, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "成员不存在")
+		}
+		return nil, fmt.Errorf("查询团队成员失败: %w", err)
+	}
+	return member, nil
+}
+
+// findUser 按邮箱(包含@)或用户名查找可用的用户
+func (s *teamService) findUser(ctx context.Context, identifier string) (*models.User, error) {
+	var user *models.User
+	var err error
+	if strings.Contains(identifier, "@") {
+		user, err = s.users.GetByEmail(ctx, identifier)
+	} else {
+		user, err = s.users.GetByUsername(ctx, identifier)
+	}
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "用户不存在")
+		}
+		return nil, fmt.Errorf("查询用户失败: %w", err)
+	}
+	if !user.IsActive() {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "该用户账户不可用")
+	}
+	return user, nil
+}
+
+// isAssignableRole 检查是否为可以直接授予的角色，所有者只能通过转让获得
+func isAssignableRole(role string) bool {
+	switch role {
+	case models.TeamRoleAdmin, models.TeamRoleMember, models.TeamRoleViewer:
+		return true
+	default:
+		return false
+	}
+}
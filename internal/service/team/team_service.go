@@ -0,0 +1,272 @@
+package team
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/cache"
+	"cloudpan/internal/pkg/config"
+	"cloudpan/internal/repository/models"
+	teamrepo "cloudpan/internal/repository/team"
+)
+
+// 团队默认限制
+const (
+	maxDescriptionLength = 500
+	maxTeamMembersLimit  = 1000
+	maxTeamPageSize      = 100
+
+	// DefaultTeamRestoreWindow 删除的团队默认可以恢复的时长
+	DefaultTeamRestoreWindow = 30 * 24 * time.Hour
+)
+
+// TeamService 团队服务接口
+//
+// 用户创建团队后成为所有者，团队成员分为四种角色：
+//   - owner：所有者，唯一，可以删除团队、转让所有权和任命管理员
+//   - admin：管理员，可以修改团队信息、邀请和移除普通成员与只读成员
+//   - member：普通成员，可以把自己的文件共享到团队
+//   - viewer：只读成员，只能查看团队文件
+//
+// 非成员访问团队时按团队不存在处理，不暴露团队是否存在。
+// 成员角色缓存在Redis中(团队ID+用户ID)，成员加入、移除、角色变更和团队删除、恢复时清除对应缓存
+//
+// 删除团队时团队和活跃成员先归档：恢复期限内成员只能查看团队、成员和团队文件，
+// 团队授予的文件权限只保留查看，所有者可以恢复团队；过期后由维护任务彻底删除
+//
+// 使用示例：
+//
+//	service := NewTeamService(teamRepo, userRepo, fileRepo, cacheManager, TeamOptionsFromConfig(cfg.Team), logger)
+//	info, err := service.CreateTeam(ctx, userID, &CreateTeamRequest{Name: "设计组"})
+//	member, err := service.InviteMember(ctx, userID, info.ID, &InviteMemberRequest{User: "alice@example.com", Role: models.TeamRoleMember})
+//	files, total, err := service.ListFiles(ctx, userID, info.ID, 1, 20)
+type TeamService interface {
+	// 团队管理
+	CreateTeam(ctx context.Context, userID uint, req *CreateTeamRequest) (*TeamInfo, error)
+	ListTeams(ctx context.Context, userID uint, page, pageSize int) ([]*TeamInfo, int64, error)
+	GetTeam(ctx context.Context, userID, teamID uint) (*TeamInfo, error)
+	UpdateTeam(ctx context.Context, userID, teamID uint, req *UpdateTeamRequest) (*TeamInfo, error)
+	DeleteTeam(ctx context.Context, userID, teamID uint) error
+	RestoreTeam(ctx context.Context, userID, teamID uint) (*TeamInfo, error)
+	PurgeArchived(ctx context.Context, limit int) (int64, error)
+
+	// 成员管理
+	ListMembers(ctx context.Context, userID, teamID uint) ([]*MemberInfo, error)
+	InviteMember(ctx context.Context, userID, teamID uint, req *InviteMemberRequest) (*MemberInfo, error)
+	UpdateMemberRole(ctx context.Context, userID, teamID, memberID uint, role string) (*MemberInfo, error)
+	RemoveMember(ctx context.Context, userID, teamID, memberID uint) error
+
+	// 团队文件
+	ListFiles(ctx context.Context, userID, teamID uint, page, pageSize int) ([]*TeamFileInfo, int64, error)
+	ShareFile(ctx context.Context, userID, teamID uint, req *ShareFileRequest) (*TeamFileInfo, error)
+	RemoveFile(ctx context.Context, userID, teamID, fileID uint) error
+}
+
+// UserFinder 按ID、邮箱或用户名查询用户，由用户仓储实现
+type UserFinder interface {
+	GetByID(ctx context.Context, id uint) (*models.User, error)
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	GetByUsername(ctx context.Context, username string) (*models.User, error)
+}
+
+// FileReader 读取文件记录，由文件仓储实现
+type FileReader interface {
+	GetByID(ctx context.Context, id uint) (*models.File, error)
+}
+
+// MembershipCache 成员角色缓存，由 cache.CacheManager 实现
+type MembershipCache interface {
+	Get(key string, dest interface{}) error
+	SetWithTTL(key string, value interface{}, ttl time.Duration) error
+	Delete(keys ...string) error
+}
+
+// TeamOptions 团队服务选项
+type TeamOptions struct {
+	RestoreWindow time.Duration // 删除后可恢复的时长
+}
+
+// TeamOptionsFromConfig 从配置构建团队服务选项
+func TeamOptionsFromConfig(cfg config.TeamConfig) TeamOptions {
+	return TeamOptions{RestoreWindow: cfg.RestoreWindow}
+}
+
+// CreateTeamRequest 创建团队请求
+type CreateTeamRequest struct {
+	Name         string  `json:"name" binding:"required"` // 团队名称
+	Description  *string `json:"description"`             // 团队描述
+	IsPublic     bool    `json:"is_public"`               // 是否公开团队
+	JoinApproval *bool   `json:"join_approval"`           // 是否需要审批加入，默认需要
+}
+
+// UpdateTeamRequest 更新团队请求，为nil的字段保持不变
+type UpdateTeamRequest struct {
+	Name         *string `json:"name"`          // 团队名称
+	Description  *string `json:"description"`   // 团队描述，空字符串表示清除
+	Avatar       *string `json:"avatar"`        // 团队头像URL，空字符串表示清除
+	IsPublic     *bool   `json:"is_public"`     // 是否公开团队
+	JoinApproval *bool   `json:"join_approval"` // 是否需要审批加入
+	MaxMembers   *int    `json:"max_members"`   // 最大成员数量，不能少于当前成员数
+}
+
+// InviteMemberRequest 邀请成员请求
+type InviteMemberRequest struct {
+	User string `json:"user" binding:"required"` // 被邀请用户的邮箱或用户名
+	Role string `json:"role"`                    // 成员角色(admin/member/viewer)，默认member
+}
+
+// UpdateMemberRoleRequest 修改成员角色请求
+type UpdateMemberRoleRequest struct {
+	Role string `json:"role" binding:"required"` // 新角色(owner/admin/member/viewer)，owner表示转让所有权
+}
+
+// ShareFileRequest 共享文件到团队请求
+type ShareFileRequest struct {
+	FileID     uint       `json:"file_id" binding:"required"` // 文件ID，只能共享自己的文件
+	Permission string     `json:"permission"`                 // 成员对文件的权限(view/download/edit/manage)，默认view
+	ExpiresAt  *time.Time `json:"expires_at"`                 // 共享过期时间，为空表示不过期
+	Note       *string    `json:"note"`                       // 共享备注
+}
+
+// TeamInfo 团队信息
+type TeamInfo struct {
+	ID           uint       `json:"id"`                    // 团队ID
+	UUID         string     `json:"uuid"`                  // 团队UUID
+	Name         string     `json:"name"`                  // 团队名称
+	Description  *string    `json:"description,omitempty"` // 团队描述
+	Avatar       *string    `json:"avatar,omitempty"`      // 团队头像URL
+	OwnerID      uint       `json:"owner_id"`              // 所有者ID
+	IsPublic     bool       `json:"is_public"`             // 是否公开团队
+	JoinApproval bool       `json:"join_approval"`         // 是否需要审批加入
+	MaxMembers   int        `json:"max_members"`           // 最大成员数量
+	MemberCount  int        `json:"member_count"`          // 成员数量
+	FileCount    int64      `json:"file_count"`            // 文件数量
+	Role         string     `json:"role"`                  // 当前用户在团队中的角色
+	Status       string     `json:"status"`                // 团队状态，archived表示已删除
+	ArchivedAt   *time.Time `json:"archived_at,omitempty"` // 删除时间
+	PurgeAt      *time.Time `json:"purge_at,omitempty"`    // 恢复期限，之后彻底删除
+	CreatedAt    time.Time  `json:"created_at"`            // 创建时间
+}
+
+// MemberInfo 团队成员信息
+type MemberInfo struct {
+	UserID      uint       `json:"user_id"`                // 用户ID
+	Username    string     `json:"username"`               // 用户名
+	DisplayName *string    `json:"display_name,omitempty"` // 显示名称
+	AvatarURL   *string    `json:"avatar_url,omitempty"`   // 头像URL
+	Role        string     `json:"role"`                   // 成员角色
+	Status      string     `json:"status"`                 // 成员状态
+	InvitedBy   *uint      `json:"invited_by,omitempty"`   // 邀请人ID
+	JoinedAt    *time.Time `json:"joined_at,omitempty"`    // 加入时间
+}
+
+// TeamFileInfo 团队文件信息
+type TeamFileInfo struct {
+	FileID     uint       `json:"file_id"`              // 文件ID
+	Name       string     `json:"name"`                 // 文件名
+	Size       int64      `json:"size"`                 // 文件大小
+	IsFolder   bool       `json:"is_folder"`            // 是否文件夹
+	MimeType   string     `json:"mime_type,omitempty"`  // MIME类型
+	SharedBy   uint       `json:"shared_by"`            // 共享者ID
+	Permission string     `json:"permission"`           // 成员对文件的权限
+	Note       *string    `json:"note,omitempty"`       // 共享备注
+	SharedAt   time.Time  `json:"shared_at"`            // 共享时间
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"` // 共享过期时间
+}
+
+// teamService 团队服务实现
+type teamService struct {
+	teams   teamrepo.TeamRepository
+	users   UserFinder
+	files   FileReader
+	cache   MembershipCache
+	keys    *cache.KeyBuilder
+	ttl     time.Duration
+	options TeamOptions
+	logger  *zap.Logger
+	now     func() time.Time
+}
+
+// NewTeamService 创建团队服务实例
+//
+// membershipCache 可以为nil(如Redis未初始化)，此时每次权限检查都查询数据库；
+// 恢复期限未配置时使用 DefaultTeamRestoreWindow
+func NewTeamService(teams teamrepo.TeamRepository, users UserFinder, files FileReader, membershipCache MembershipCache, options TeamOptions, logger *zap.Logger) TeamService {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	if options.RestoreWindow <= 0 {
+		options.RestoreWindow = DefaultTeamRestoreWindow
+	}
+	return &teamService{
+		teams:   teams,
+		users:   users,
+		files:   files,
+		cache:   membershipCache,
+		keys:    cache.NewKeyBuilder(),
+		ttl:     cache.NewTTLManager().GetTTL("team_members"),
+		options: options,
+		logger:  logger,
+		now:     time.Now,
+	}
+}
+
+// toTeamInfo 转换团队信息，role 为当前用户的角色
+func toTeamInfo(team *models.Team, role string) *TeamInfo {
+	return &TeamInfo{
+		ID:           team.ID,
+		UUID:         team.UUID,
+		Name:         team.Name,
+		Description:  team.Description,
+		Avatar:       team.Avatar,
+		OwnerID:      team.OwnerID,
+		IsPublic:     team.IsPublic,
+		JoinApproval: team.JoinApproval,
+		MaxMembers:   team.MaxMembers,
+		MemberCount:  team.MemberCount,
+		FileCount:    team.FileCount,
+		Role:         role,
+		Status:       team.Status,
+		ArchivedAt:   team.ArchivedAt,
+		PurgeAt:      team.PurgeAt,
+		CreatedAt:    team.CreatedAt,
+	}
+}
+
+// toMemberInfo 转换成员信息，user 为nil时只包含成员记录中的字段
+func toMemberInfo(member *models.TeamMember, user *models.User) *MemberInfo {
+	info := &MemberInfo{
+		UserID:    member.UserID,
+		Role:      member.Role,
+		Status:    member.Status,
+		InvitedBy: member.InvitedBy,
+		JoinedAt:  member.JoinedAt,
+	}
+	if user != nil {
+		info.Username = user.Username
+		info.DisplayName = user.DisplayName
+		info.AvatarURL = user.AvatarURL
+	}
+	return info
+}
+
+// toTeamFileInfo 转换团队文件信息
+func toTeamFileInfo(shared *models.TeamFile, file *models.File) *TeamFileInfo {
+	info := &TeamFileInfo{
+		FileID:     shared.FileID,
+		Name:       file.Name,
+		Size:       file.Size,
+		IsFolder:   file.IsFolder,
+		SharedBy:   shared.SharedBy,
+		Permission: shared.Permission,
+		Note:       shared.ShareNote,
+		SharedAt:   shared.SharedAt,
+		ExpiresAt:  shared.ExpiresAt,
+	}
+	if file.MimeType != nil {
+		info.MimeType = *file.MimeType
+	}
+	return info
+}
@@ -0,0 +1,429 @@
+package team
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+)
+
+// defaultMaxMembers 新建团队的最大成员数量
+const defaultMaxMembers = 50
+
+// CreateTeam 创建团队，创建者成为所有者
+func (s *teamService) CreateTeam(ctx context.Context, userID uint, req *CreateTeamRequest) (*TeamInfo, error) {
+	if userID == 0 || req == nil {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "用户ID和团队名称不能为空")
+	}
+	name, err := normalizeTeamName(req.Name)
+	if err != nil {
+		return nil, err
+	}
+	description, err := normalizeDescription(req.Description)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	team := &models.Team{
+		Name:         name,
+		Description:  description,
+		OwnerID:      userID,
+		IsPublic:     req.IsPublic,
+		JoinApproval: true,
+		MaxMembers:   defaultMaxMembers,
+		Status:       models.TeamStatusActive,
+		MemberCount:  1,
+	}
+	owner := &models.TeamMember{
+		UserID:   userID,
+		Role:     models.TeamRoleOwner,
+		Status:   models.TeamMemberStatusActive,
+		JoinedAt: &now,
+	}
+	if err := s.teams.Create(ctx, team, owner); err != nil {
+		return nil, fmt.Errorf("创建团队失败: %w", err)
+	}
+	s.forgetMembers(team.ID, userID)
+
+	s.logger.Info("Team created",
+		zap.Uint("team_id", team.ID),
+		zap.Uint("owner_id", userID))
+	return toTeamInfo(team, models.TeamRoleOwner), nil
+}
+
+// ListTeams 分页列出用户加入的团队，按加入顺序排列
+func (s *teamService) ListTeams(ctx context.Context, userID uint, page, pageSize int) ([]*TeamInfo, int64, error) {
+	if userID == 0 {
+		return nil, 0, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "用户ID不能为空")
+	}
+	page, pageSize = normalizePage(page, pageSize)
+
+	memberships, total, err := s.teams.ListByMember(ctx, userID, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("获取团队列表失败: %w", err)
+	}
+	infos := make([]*TeamInfo, 0, len(memberships))
+	for _, membership := range memberships {
+		infos = append(infos, toTeamInfo(&membership.Team, membership.Role))
+	}
+	return infos, total, nil
+}
+
+// GetTeam 获取团队信息，团队的任何成员都可以查看
+func (s *teamService) GetTeam(ctx context.Context, userID, teamID uint) (*TeamInfo, error) {
+	actor, err := s.authorize(ctx, teamID, userID)
+	if err != nil {
+		return nil, err
+	}
+	team, err := s.getTeam(ctx, teamID)
+	if err != nil {
+		return nil, err
+	}
+	return toTeamInfo(team, actor.Role), nil
+}
+
+// UpdateTeam 更新团队信息和设置，只有所有者和管理员可以修改
+func (s *teamService) UpdateTeam(ctx context.Context, userID, teamID uint, req *UpdateTeamRequest) (*TeamInfo, error) {
+	if req == nil {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "更新内容不能为空")
+	}
+	actor, err := s.authorizeWrite(ctx, teamID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !actor.IsAdmin() {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, "只有团队所有者和管理员可以修改团队信息")
+	}
+	team, err := s.getTeam(ctx, teamID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		if team.Name, err = normalizeTeamName(*req.Name); err != nil {
+			return nil, err
+		}
+	}
+	if req.Description != nil {
+		if team.Description, err = normalizeDescription(req.Description); err != nil {
+			return nil, err
+		}
+	}
+	if req.Avatar != nil {
+		avatar := strings.TrimSpace(*req.Avatar)
+		switch {
+		case avatar == "":
+			team.Avatar = nil
+		case len(avatar) > 500:
+			return nil, pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "团队头像URL长度不能超过500")
+		default:
+			team.Avatar = &avatar
+		}
+	}
+	if req.IsPublic != nil {
+		team.IsPublic = *req.IsPublic
+	}
+	if req.JoinApproval != nil {
+		team.JoinApproval = *req.JoinApproval
+	}
+	if req.MaxMembers != nil {
+		maxMembers := *req.MaxMembers
+		if maxMembers < team.MemberCount || maxMembers < 1 || maxMembers > maxTeamMembersLimit {
+			return nil, pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput,
+				"最大成员数量需在当前成员数 %d 和 %d 之间", team.MemberCount, maxTeamMembersLimit)
+		}
+		team.MaxMembers = maxMembers
+	}
+
+	if err := s.teams.Update(ctx, team); err != nil {
+		return nil, fmt.Errorf("更新团队失败: %w", err)
+	}
+	return toTeamInfo(team, actor.Role), nil
+}
+
+// DeleteTeam 删除团队，只有所有者可以删除
+//
+// 团队和活跃成员归档：恢复期限内成员只能查看，团队文件和授予团队的文件权限保留但冻结为只读，
+// 期限过后由 PurgeArchived 彻底删除，团队文件只解除共享，文件本身不受影响
+func (s *teamService) DeleteTeam(ctx context.Context, userID, teamID uint) error {
+	actor, err := s.authorizeWrite(ctx, teamID, userID)
+	if err != nil {
+		return err
+	}
+	if !actor.IsOwner() {
+		return pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, "只有团队所有者可以删除团队")
+	}
+
+	members, err := s.teams.ListMembers(ctx, teamID)
+	if err != nil {
+		return fmt.Errorf("获取团队成员失败: %w", err)
+	}
+	now := s.now()
+	purgeAt := now.Add(s.options.RestoreWindow)
+	if err := s.teams.Archive(ctx, teamID, userID, now, purgeAt); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "团队不存在")
+		}
+		return fmt.Errorf("删除团队失败: %w", err)
+	}
+	s.forgetMembers(teamID, memberIDs(members)...)
+
+	s.logger.Info("Team archived",
+		zap.Uint("team_id", teamID),
+		zap.Uint("owner_id", userID),
+		zap.Int("members", len(members)),
+		zap.Time("purge_at", purgeAt))
+	return nil
+}
+
+// RestoreTeam 恢复已删除的团队，只有所有者可以在恢复期限内恢复，归档的成员恢复为活跃
+func (s *teamService) RestoreTeam(ctx context.Context, userID, teamID uint) (*TeamInfo, error) {
+	actor, err := s.authorize(ctx, teamID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !actor.IsOwner() {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, "只有团队所有者可以恢复团队")
+	}
+	team, err := s.getTeam(ctx, teamID)
+	if err != nil {
+		return nil, err
+	}
+	if !team.IsArchived() {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "团队未被删除")
+	}
+	if team.PurgeAt != nil && !s.now().Before(*team.PurgeAt) {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "团队已超过恢复期限")
+	}
+
+	members, err := s.teams.ListMembers(ctx, teamID)
+	if err != nil {
+		return nil, fmt.Errorf("获取团队成员失败: %w", err)
+	}
+	if err := s.teams.Restore(ctx, teamID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "团队未被删除")
+		}
+		return nil, fmt.Errorf("恢复团队失败: %w", err)
+	}
+	s.forgetMembers(teamID, memberIDs(members)...)
+
+	s.logger.Info("Team restored",
+		zap.Uint("team_id", teamID),
+		zap.Uint("owner_id", userID),
+		zap.Int("members", len(members)))
+	team.Status = models.TeamStatusActive
+	team.ArchivedAt, team.ArchivedBy, team.PurgeAt = nil, nil, nil
+	return toTeamInfo(team, actor.Role), nil
+}
+
+// PurgeArchived 彻底删除超过恢复期限的团队，最多处理 limit 个，返回删除的团队数
+//
+// 由维护任务定期调用；单个团队删除失败不影响其他团队，返回最后一个错误
+func (s *teamService) PurgeArchived(ctx context.Context, limit int) (int64, error) {
+	teams, err := s.teams.ListArchivedBefore(ctx, s.now(), limit)
+	if err != nil {
+		return 0, fmt.Errorf("查询待删除的团队失败: %w", err)
+	}
+
+	var purged int64
+	var failed error
+	for _, team := range teams {
+		if ctx.Err() != nil {
+			return purged, ctx.Err()
+		}
+		members, err := s.teams.ListMembers(ctx, team.ID)
+		if err == nil {
+			err = s.teams.Delete(ctx, team.ID)
+		}
+		if err != nil {
+			failed = err
+			s.logger.Warn("Failed to purge archived team",
+				zap.Uint("team_id", team.ID),
+				zap.Error(err))
+			continue
+		}
+		s.forgetMembers(team.ID, memberIDs(members)...)
+		purged++
+		s.logger.Info("Team purged",
+			zap.Uint("team_id", team.ID),
+			zap.Int("members", len(members)))
+	}
+	return purged, failed
+}
+
+// ListFiles 分页列出团队文件，按共享时间倒序；团队的任何成员都可以查看
+func (s *teamService) ListFiles(ctx context.Context, userID, teamID uint, page, pageSize int) ([]*TeamFileInfo, int64, error) {
+	if _, err := s.authorize(ctx, teamID, userID); err != nil {
+		return nil, 0, err
+	}
+	page, pageSize = normalizePage(page, pageSize)
+
+	shared, total, err := s.teams.ListFiles(ctx, teamID, s.now(), pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("获取团队文件失败: %w", err)
+	}
+	infos := make([]*TeamFileInfo, 0, len(shared))
+	for _, item := range shared {
+		infos = append(infos, toTeamFileInfo(item, &item.File))
+	}
+	return infos, total, nil
+}
+
+// ShareFile 将自己的文件共享到团队，只读成员不能共享
+func (s *teamService) ShareFile(ctx context.Context, userID, teamID uint, req *ShareFileRequest) (*TeamFileInfo, error) {
+	if req == nil || req.FileID == 0 {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "文件ID不能为空")
+	}
+	actor, err := s.authorizeWrite(ctx, teamID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !actor.CanManageFiles() {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, "只读成员不能共享文件到团队")
+	}
+
+	permission := req.Permission
+	if permission == "" {
+		permission = models.TeamFilePermissionView
+	}
+	switch permission {
+	case models.TeamFilePermissionView, models.TeamFilePermissionDownload,
+		models.TeamFilePermissionEdit, models.TeamFilePermissionManage:
+	default:
+		return nil, pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "不支持的文件权限 %q", permission)
+	}
+	now := s.now()
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(now) {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "过期时间必须晚于当前时间")
+	}
+	note, err := normalizeDescription(req.Note)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := s.files.GetByID(ctx, req.FileID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("获取文件失败: %w", err)
+	}
+	// 不是自己的文件按不存在处理，不暴露其他用户的文件
+	if file == nil || file.UserID != userID || !file.IsActive() {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "文件不存在")
+	}
+
+	shared := &models.TeamFile{
+		TeamID:     teamID,
+		FileID:     file.ID,
+		SharedBy:   userID,
+		Permission: permission,
+		IsWritable: permission == models.TeamFilePermissionEdit || permission == models.TeamFilePermissionManage,
+		Status:     models.TeamFileStatusActive,
+		SharedAt:   now,
+		ExpiresAt:  req.ExpiresAt,
+		ShareNote:  note,
+	}
+	if err := s.teams.AddFile(ctx, shared); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceExists, "文件已共享到该团队")
+		}
+		return nil, fmt.Errorf("共享文件到团队失败: %w", err)
+	}
+
+	s.logger.Info("File shared to team",
+		zap.Uint("team_id", teamID),
+		zap.Uint("file_id", file.ID),
+		zap.Uint("user_id", userID),
+		zap.String("permission", permission))
+	return toTeamFileInfo(shared, file), nil
+}
+
+// RemoveFile 将文件移出团队，共享者本人、所有者和管理员可以移除
+func (s *teamService) RemoveFile(ctx context.Context, userID, teamID, fileID uint) error {
+	actor, err := s.authorizeWrite(ctx, teamID, userID)
+	if err != nil {
+		return err
+	}
+	shared, err := s.teams.GetFile(ctx, teamID, fileID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "团队文件不存在")
+		}
+		return fmt.Errorf("获取团队文件失败: %w", err)
+	}
+	if shared.SharedBy != userID && !actor.IsAdmin() {
+		return pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, "只能移除自己共享的文件")
+	}
+
+	if err := s.teams.RemoveFile(ctx, teamID, fileID); err != nil {
+		return fmt.Errorf("移除团队文件失败: %w", err)
+	}
+	return nil
+}
+
+// memberIDs 返回成员的用户ID
+func memberIDs(members []*models.TeamMember) []uint {
+	userIDs := make([]uint, 0, len(members))
+	for _, member := range members {
+		userIDs = append(userIDs, member.UserID)
+	}
+	return userIDs
+}
+
+// getTeam 获取团队，不存在时返回资源不存在错误
+func (s *teamService) getTeam(ctx context.Context, teamID uint) (*models.Team, error) {
+	team, err := s.teams.GetByID(ctx, teamID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "团队不存在")
+		}
+		return nil, fmt.Errorf("获取团队失败: %w", err)
+	}
+	return team, nil
+}
+
+// normalizeTeamName 规范化并校验团队名称
+func normalizeTeamName(name string) (string, error) {
+	name = utils.SanitizeDisplayText(name)
+	if err := utils.NewValidator().ValidateTeamName(name); err != nil {
+		return "", pkgErrors.WrapError(pkgErrors.ErrInvalidInput, err.Error())
+	}
+	return name, nil
+}
+
+// normalizeDescription 去除首尾空白并校验长度，为空时返回nil
+func normalizeDescription(text *string) (*string, error) {
+	if text == nil {
+		return nil, nil
+	}
+	value := strings.TrimSpace(*text)
+	if value == "" {
+		return nil, nil
+	}
+	if utf8.RuneCountInString(value) > maxDescriptionLength {
+		return nil, pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "描述长度不能超过%d个字符", maxDescriptionLength)
+	}
+	return &value, nil
+}
+
+// normalizePage 规范化分页参数
+func normalizePage(page, pageSize int) (int, int) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 20
+	}
+	if pageSize > maxTeamPageSize {
+		pageSize = maxTeamPageSize
+	}
+	return page, pageSize
+}
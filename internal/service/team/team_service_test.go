@@ -0,0 +1,568 @@
+package team
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/cache"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/repository/models"
+)
+
+// memoryTeams 内存团队仓储
+type memoryTeams struct {
+	teams      map[uint]*models.Team
+	members    map[uint]map[uint]*models.TeamMember
+	files      map[uint]map[uint]*models.TeamFile
+	nextID     uint
+	memberHits int
+}
+
+func newMemoryTeams() *memoryTeams {
+	return &memoryTeams{
+		teams:   map[uint]*models.Team{},
+		members: map[uint]map[uint]*models.TeamMember{},
+		files:   map[uint]map[uint]*models.TeamFile{},
+	}
+}
+
+func (m *memoryTeams) Create(_ context.Context, team *models.Team, owner *models.TeamMember) error {
+	m.nextID++
+	team.ID = m.nextID
+	owner.TeamID = team.ID
+	copied := *team
+	m.teams[team.ID] = &copied
+	m.members[team.ID] = map[uint]*models.TeamMember{owner.UserID: owner}
+	m.files[team.ID] = map[uint]*models.TeamFile{}
+	return nil
+}
+
+func (m *memoryTeams) GetByID(_ context.Context, id uint) (*models.Team, error) {
+	team, ok := m.teams[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *team
+	return &copied, nil
+}
+
+func (m *memoryTeams) Update(_ context.Context, team *models.Team) error {
+	copied := *team
+	m.teams[team.ID] = &copied
+	return nil
+}
+
+func (m *memoryTeams) Delete(_ context.Context, id uint) error {
+	delete(m.teams, id)
+	delete(m.members, id)
+	delete(m.files, id)
+	return nil
+}
+
+func (m *memoryTeams) Archive(_ context.Context, id, archivedBy uint, archivedAt, purgeAt time.Time) error {
+	team, ok := m.teams[id]
+	if !ok || team.IsArchived() {
+		return gorm.ErrRecordNotFound
+	}
+	team.Status = models.TeamStatusArchived
+	team.ArchivedAt, team.ArchivedBy, team.PurgeAt = &archivedAt, &archivedBy, &purgeAt
+	for _, member := range m.members[id] {
+		if member.IsActive() {
+			member.Status = models.TeamMemberStatusArchived
+		}
+	}
+	return nil
+}
+
+func (m *memoryTeams) Restore(_ context.Context, id uint) error {
+	team, ok := m.teams[id]
+	if !ok || !team.IsArchived() {
+		return gorm.ErrRecordNotFound
+	}
+	team.Status = models.TeamStatusActive
+	team.ArchivedAt, team.ArchivedBy, team.PurgeAt = nil, nil, nil
+	for _, member := range m.members[id] {
+		if member.Status == models.TeamMemberStatusArchived {
+			member.Status = models.TeamMemberStatusActive
+		}
+	}
+	return nil
+}
+
+func (m *memoryTeams) ListArchivedBefore(_ context.Context, before time.Time, limit int) ([]*models.Team, error) {
+	var teams []*models.Team
+	for _, team := range m.teams {
+		if team.IsArchived() && !team.PurgeAt.After(before) && len(teams) < limit {
+			copied := *team
+			teams = append(teams, &copied)
+		}
+	}
+	return teams, nil
+}
+
+func (m *memoryTeams) ListArchivedTeamIDsByMember(_ context.Context, userID uint) ([]uint, error) {
+	var teamIDs []uint
+	for teamID, members := range m.members {
+		if member, ok := members[userID]; ok && member.Status == models.TeamMemberStatusArchived {
+			teamIDs = append(teamIDs, teamID)
+		}
+	}
+	return teamIDs, nil
+}
+
+func (m *memoryTeams) ListByMember(_ context.Context, userID uint, limit, offset int) ([]*models.TeamMember, int64, error) {
+	var memberships []*models.TeamMember
+	for teamID, members := range m.members {
+		if member, ok := members[userID]; ok && (member.IsActive() || member.Status == models.TeamMemberStatusArchived) {
+			copied := *member
+			copied.Team = *m.teams[teamID]
+			memberships = append(memberships, &copied)
+		}
+	}
+	sort.Slice(memberships, func(i, j int) bool { return memberships[i].TeamID < memberships[j].TeamID })
+	total := int64(len(memberships))
+	if offset >= len(memberships) {
+		return nil, total, nil
+	}
+	return memberships[offset:min(offset+limit, len(memberships))], total, nil
+}
+
+func (m *memoryTeams) ListTeamRolesByMember(_ context.Context, userID uint) (map[uint]string, error) {
+	roles := make(map[uint]string)
+	for teamID, members := range m.members {
+		if member, ok := members[userID]; ok && member.IsActive() {
+			roles[teamID] = member.Role
+		}
+	}
+	return roles, nil
+}
+
+func (m *memoryTeams) GetMember(_ context.Context, teamID, userID uint) (*models.TeamMember, error) {
+	m.memberHits++
+	member, ok := m.members[teamID][userID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *member
+	return &copied, nil
+}
+
+func (m *memoryTeams) ListMembers(_ context.Context, teamID uint) ([]*models.TeamMember, error) {
+	var members []*models.TeamMember
+	for _, member := range m.members[teamID] {
+		copied := *member
+		copied.User = models.User{Username: "user"}
+		members = append(members, &copied)
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].UserID < members[j].UserID })
+	return members, nil
+}
+
+func (m *memoryTeams) AddMember(_ context.Context, member *models.TeamMember, check func(team *models.Team) error) error {
+	team, ok := m.teams[member.TeamID]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	if err := check(team); err != nil {
+		return err
+	}
+	if _, ok := m.members[team.ID][member.UserID]; ok {
+		return gorm.ErrDuplicatedKey
+	}
+	copied := *member
+	m.members[team.ID][member.UserID] = &copied
+	team.MemberCount = len(m.members[team.ID])
+	return nil
+}
+
+func (m *memoryTeams) UpdateMemberRole(_ context.Context, teamID, userID uint, role string) error {
+	member, ok := m.members[teamID][userID]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	member.Role = role
+	return nil
+}
+
+func (m *memoryTeams) RemoveMember(_ context.Context, teamID, userID uint) error {
+	delete(m.members[teamID], userID)
+	m.teams[teamID].MemberCount = len(m.members[teamID])
+	return nil
+}
+
+func (m *memoryTeams) TransferOwnership(_ context.Context, teamID, fromUserID, toUserID uint) error {
+	m.teams[teamID].OwnerID = toUserID
+	m.members[teamID][fromUserID].Role = models.TeamRoleAdmin
+	m.members[teamID][toUserID].Role = models.TeamRoleOwner
+	return nil
+}
+
+func (m *memoryTeams) AddFile(_ context.Context, file *models.TeamFile) error {
+	if _, ok := m.files[file.TeamID][file.FileID]; ok {
+		return gorm.ErrDuplicatedKey
+	}
+	copied := *file
+	m.files[file.TeamID][file.FileID] = &copied
+	return nil
+}
+
+func (m *memoryTeams) GetFile(_ context.Context, teamID, fileID uint) (*models.TeamFile, error) {
+	file, ok := m.files[teamID][fileID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *file
+	return &copied, nil
+}
+
+func (m *memoryTeams) RemoveFile(_ context.Context, teamID, fileID uint) error {
+	delete(m.files[teamID], fileID)
+	return nil
+}
+
+func (m *memoryTeams) ListFiles(_ context.Context, teamID uint, now time.Time, limit, offset int) ([]*models.TeamFile, int64, error) {
+	var files []*models.TeamFile
+	for _, file := range m.files[teamID] {
+		if file.ExpiresAt == nil || file.ExpiresAt.After(now) {
+			copied := *file
+			copied.File = models.File{Name: "file", Size: 1}
+			files = append(files, &copied)
+		}
+	}
+	total := int64(len(files))
+	if offset >= len(files) {
+		return nil, total, nil
+	}
+	return files[offset:min(offset+limit, len(files))], total, nil
+}
+
+// memoryUsers 内存用户仓储
+type memoryUsers map[uint]*models.User
+
+func (m memoryUsers) GetByID(_ context.Context, id uint) (*models.User, error) {
+	if user, ok := m[id]; ok {
+		return user, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m memoryUsers) GetByEmail(_ context.Context, email string) (*models.User, error) {
+	for _, user := range m {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m memoryUsers) GetByUsername(_ context.Context, username string) (*models.User, error) {
+	for _, user := range m {
+		if user.Username == username {
+			return user, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// memoryFiles 内存文件仓储
+type memoryFiles map[uint]*models.File
+
+func (m memoryFiles) GetByID(_ context.Context, id uint) (*models.File, error) {
+	if file, ok := m[id]; ok {
+		return file, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// memoryCache 内存缓存，按JSON保存值
+type memoryCache map[string]string
+
+func (m memoryCache) Get(key string, dest interface{}) error {
+	data, ok := m[key]
+	if !ok {
+		return cache.ErrCacheNotFound
+	}
+	return json.Unmarshal([]byte(data), dest)
+}
+
+func (m memoryCache) SetWithTTL(key string, value interface{}, _ time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	m[key] = string(data)
+	return nil
+}
+
+func (m memoryCache) Delete(keys ...string) error {
+	for _, key := range keys {
+		delete(m, key)
+	}
+	return nil
+}
+
+// 测试用户：1为团队创建者，2、3、4为其他用户
+const (
+	ownerID uint = 1
+	aliceID uint = 2
+	bobID   uint = 3
+	carolID uint = 4
+)
+
+// teamFixture 团队服务测试环境
+type teamFixture struct {
+	service TeamService
+	teams   *memoryTeams
+	cache   memoryCache
+	team    *TeamInfo
+}
+
+func newTeamFixture(t *testing.T) *teamFixture {
+	t.Helper()
+	users := memoryUsers{}
+	for id, name := range map[uint]string{ownerID: "owner", aliceID: "alice", bobID: "bob", carolID: "carol"} {
+		users[id] = &models.User{Username: name, Email: name + "@example.com", Status: "active"}
+		users[id].ID = id
+	}
+	files := memoryFiles{}
+	for id, userID := range map[uint]uint{10: ownerID, 11: aliceID, 12: bobID} {
+		files[id] = &models.File{UserID: userID, Name: "file", Status: "active"}
+		files[id].ID = id
+	}
+
+	teams := newMemoryTeams()
+	memCache := memoryCache{}
+	service := NewTeamService(teams, users, files, memCache, TeamOptions{}, nil)
+	team, err := service.CreateTeam(context.Background(), ownerID, &CreateTeamRequest{Name: "设计组"})
+	require.NoError(t, err)
+	return &teamFixture{service: service, teams: teams, cache: memCache, team: team}
+}
+
+func (f *teamFixture) invite(t *testing.T, user, role string) {
+	t.Helper()
+	_, err := f.service.InviteMember(context.Background(), ownerID, f.team.ID, &InviteMemberRequest{User: user, Role: role})
+	require.NoError(t, err)
+}
+
+func TestTeamService_CreateTeam(t *testing.T) {
+	ctx := context.Background()
+	f := newTeamFixture(t)
+	assert.Equal(t, models.TeamRoleOwner, f.team.Role)
+	assert.Equal(t, defaultMaxMembers, f.team.MaxMembers)
+	assert.True(t, f.team.JoinApproval)
+
+	_, err := f.service.CreateTeam(ctx, ownerID, &CreateTeamRequest{Name: "x"})
+	assert.True(t, pkgErrors.IsValidationError(err))
+
+	teams, total, err := f.service.ListTeams(ctx, ownerID, 1, 20)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, total)
+	assert.Equal(t, "设计组", teams[0].Name)
+
+	// 非成员按团队不存在处理
+	_, err = f.service.GetTeam(ctx, aliceID, f.team.ID)
+	assert.True(t, pkgErrors.IsNotFoundError(err))
+}
+
+func TestTeamService_InviteMember(t *testing.T) {
+	ctx := context.Background()
+	f := newTeamFixture(t)
+
+	member, err := f.service.InviteMember(ctx, ownerID, f.team.ID, &InviteMemberRequest{User: "alice@example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, models.TeamRoleMember, member.Role)
+	assert.Equal(t, "alice", member.Username)
+	require.NotNil(t, member.InvitedBy)
+	assert.Equal(t, ownerID, *member.InvitedBy)
+
+	_, err = f.service.InviteMember(ctx, ownerID, f.team.ID, &InviteMemberRequest{User: "alice"})
+	assert.True(t, errors.Is(err, pkgErrors.ErrResourceExists))
+	_, err = f.service.InviteMember(ctx, ownerID, f.team.ID, &InviteMemberRequest{User: "nobody"})
+	assert.True(t, pkgErrors.IsNotFoundError(err))
+	_, err = f.service.InviteMember(ctx, ownerID, f.team.ID, &InviteMemberRequest{User: "bob", Role: models.TeamRoleOwner})
+	assert.True(t, pkgErrors.IsValidationError(err))
+
+	// 普通成员不能邀请，管理员不能任命管理员
+	_, err = f.service.InviteMember(ctx, aliceID, f.team.ID, &InviteMemberRequest{User: "bob"})
+	assert.True(t, pkgErrors.IsPermissionError(err))
+	_, err = f.service.UpdateMemberRole(ctx, ownerID, f.team.ID, aliceID, models.TeamRoleAdmin)
+	require.NoError(t, err)
+	_, err = f.service.InviteMember(ctx, aliceID, f.team.ID, &InviteMemberRequest{User: "bob", Role: models.TeamRoleAdmin})
+	assert.True(t, pkgErrors.IsPermissionError(err))
+	_, err = f.service.InviteMember(ctx, aliceID, f.team.ID, &InviteMemberRequest{User: "bob", Role: models.TeamRoleViewer})
+	require.NoError(t, err)
+
+	// 成员数达到上限
+	f.teams.teams[f.team.ID].MaxMembers = 3
+	_, err = f.service.InviteMember(ctx, ownerID, f.team.ID, &InviteMemberRequest{User: "carol"})
+	assert.True(t, errors.Is(err, pkgErrors.ErrQuotaExceeded))
+}
+
+func TestTeamService_MemberRoles(t *testing.T) {
+	ctx := context.Background()
+	f := newTeamFixture(t)
+	f.invite(t, "alice", models.TeamRoleAdmin)
+	f.invite(t, "bob", models.TeamRoleMember)
+	f.invite(t, "carol", models.TeamRoleViewer)
+
+	// 管理员可以调整普通成员和只读成员，不能调整管理员和所有者
+	_, err := f.service.UpdateMemberRole(ctx, aliceID, f.team.ID, bobID, models.TeamRoleViewer)
+	require.NoError(t, err)
+	_, err = f.service.UpdateMemberRole(ctx, aliceID, f.team.ID, bobID, models.TeamRoleAdmin)
+	assert.True(t, pkgErrors.IsPermissionError(err))
+	_, err = f.service.UpdateMemberRole(ctx, aliceID, f.team.ID, ownerID, models.TeamRoleMember)
+	assert.True(t, pkgErrors.IsPermissionError(err))
+	_, err = f.service.UpdateMemberRole(ctx, bobID, f.team.ID, carolID, models.TeamRoleMember)
+	assert.True(t, pkgErrors.IsPermissionError(err))
+
+	// 管理员不能移除管理员，成员可以离开团队，所有者不能离开
+	_, err = f.service.UpdateMemberRole(ctx, ownerID, f.team.ID, bobID, models.TeamRoleAdmin)
+	require.NoError(t, err)
+	err = f.service.RemoveMember(ctx, aliceID, f.team.ID, bobID)
+	assert.True(t, pkgErrors.IsPermissionError(err))
+	require.NoError(t, f.service.RemoveMember(ctx, carolID, f.team.ID, carolID))
+	err = f.service.RemoveMember(ctx, ownerID, f.team.ID, ownerID)
+	assert.True(t, pkgErrors.IsPermissionError(err))
+
+	// 转让所有权后原所有者成为管理员
+	member, err := f.service.UpdateMemberRole(ctx, ownerID, f.team.ID, aliceID, models.TeamRoleOwner)
+	require.NoError(t, err)
+	assert.Equal(t, models.TeamRoleOwner, member.Role)
+	info, err := f.service.GetTeam(ctx, ownerID, f.team.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.TeamRoleAdmin, info.Role)
+	assert.Equal(t, aliceID, info.OwnerID)
+	assert.True(t, pkgErrors.IsPermissionError(f.service.DeleteTeam(ctx, ownerID, f.team.ID)))
+}
+
+func TestTeamService_MembershipCache(t *testing.T) {
+	ctx := context.Background()
+	f := newTeamFixture(t)
+	f.invite(t, "alice", models.TeamRoleMember)
+
+	_, err := f.service.GetTeam(ctx, aliceID, f.team.ID)
+	require.NoError(t, err)
+	hits := f.teams.memberHits
+	_, err = f.service.ListMembers(ctx, aliceID, f.team.ID)
+	require.NoError(t, err)
+	assert.Equal(t, hits, f.teams.memberHits, "成员角色应从缓存读取")
+
+	// 非成员的结果同样缓存，加入团队后清除
+	_, err = f.service.GetTeam(ctx, bobID, f.team.ID)
+	assert.True(t, pkgErrors.IsNotFoundError(err))
+	f.invite(t, "bob", models.TeamRoleViewer)
+	_, err = f.service.GetTeam(ctx, bobID, f.team.ID)
+	require.NoError(t, err)
+
+	// 移除后缓存失效
+	require.NoError(t, f.service.RemoveMember(ctx, ownerID, f.team.ID, aliceID))
+	_, err = f.service.GetTeam(ctx, aliceID, f.team.ID)
+	assert.True(t, pkgErrors.IsNotFoundError(err))
+
+	// 删除团队后所有成员的缓存失效，成员只能查看
+	require.NoError(t, f.service.DeleteTeam(ctx, ownerID, f.team.ID))
+	assert.NotContains(t, f.cache, cache.NewKeyBuilder().TeamPermissions("1", "3"))
+	_, _, err = f.service.ListFiles(ctx, bobID, f.team.ID, 1, 20)
+	require.NoError(t, err)
+	_, err = f.service.ShareFile(ctx, bobID, f.team.ID, &ShareFileRequest{FileID: 12})
+	assert.True(t, pkgErrors.IsPermissionError(err))
+}
+
+func TestTeamService_Files(t *testing.T) {
+	ctx := context.Background()
+	f := newTeamFixture(t)
+	f.invite(t, "alice", models.TeamRoleMember)
+	f.invite(t, "bob", models.TeamRoleViewer)
+
+	shared, err := f.service.ShareFile(ctx, aliceID, f.team.ID, &ShareFileRequest{FileID: 11, Permission: models.TeamFilePermissionEdit})
+	require.NoError(t, err)
+	assert.Equal(t, aliceID, shared.SharedBy)
+
+	_, err = f.service.ShareFile(ctx, aliceID, f.team.ID, &ShareFileRequest{FileID: 11})
+	assert.True(t, errors.Is(err, pkgErrors.ErrResourceExists))
+	_, err = f.service.ShareFile(ctx, aliceID, f.team.ID, &ShareFileRequest{FileID: 10})
+	assert.True(t, pkgErrors.IsNotFoundError(err), "不能共享其他用户的文件")
+	_, err = f.service.ShareFile(ctx, bobID, f.team.ID, &ShareFileRequest{FileID: 12})
+	assert.True(t, pkgErrors.IsPermissionError(err), "只读成员不能共享文件")
+	_, err = f.service.ShareFile(ctx, aliceID, f.team.ID, &ShareFileRequest{FileID: 11, Permission: "owner"})
+	assert.True(t, pkgErrors.IsValidationError(err))
+
+	files, total, err := f.service.ListFiles(ctx, bobID, f.team.ID, 1, 20)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, total)
+	assert.Equal(t, uint(11), files[0].FileID)
+
+	_, _, err = f.service.ListFiles(ctx, carolID, f.team.ID, 1, 20)
+	assert.True(t, pkgErrors.IsNotFoundError(err))
+
+	assert.True(t, pkgErrors.IsPermissionError(f.service.RemoveFile(ctx, bobID, f.team.ID, 11)))
+	require.NoError(t, f.service.RemoveFile(ctx, ownerID, f.team.ID, 11))
+	assert.True(t, pkgErrors.IsNotFoundError(f.service.RemoveFile(ctx, aliceID, f.team.ID, 11)))
+}
+
+func TestTeamService_DeleteAndRestore(t *testing.T) {
+	ctx := context.Background()
+	f := newTeamFixture(t)
+	f.invite(t, "alice", models.TeamRoleAdmin)
+	f.invite(t, "bob", models.TeamRoleMember)
+	_, err := f.service.ShareFile(ctx, bobID, f.team.ID, &ShareFileRequest{FileID: 12})
+	require.NoError(t, err)
+	now := time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)
+	service := f.service.(*teamService)
+	service.now = func() time.Time { return now }
+
+	assert.True(t, pkgErrors.IsPermissionError(f.service.DeleteTeam(ctx, aliceID, f.team.ID)))
+	require.NoError(t, f.service.DeleteTeam(ctx, ownerID, f.team.ID))
+	assert.True(t, pkgErrors.IsPermissionError(f.service.DeleteTeam(ctx, ownerID, f.team.ID)))
+
+	// 已删除的团队仍在列表中，成员和团队文件冻结为只读
+	teams, _, err := f.service.ListTeams(ctx, bobID, 1, 20)
+	require.NoError(t, err)
+	require.Len(t, teams, 1)
+	assert.Equal(t, models.TeamStatusArchived, teams[0].Status)
+	require.NotNil(t, teams[0].PurgeAt)
+	assert.Equal(t, now.Add(DefaultTeamRestoreWindow), *teams[0].PurgeAt)
+	files, _, err := f.service.ListFiles(ctx, bobID, f.team.ID, 1, 20)
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+	assert.True(t, pkgErrors.IsPermissionError(f.service.RemoveFile(ctx, bobID, f.team.ID, 12)))
+	assert.True(t, pkgErrors.IsPermissionError(f.service.RemoveMember(ctx, bobID, f.team.ID, bobID)))
+	_, err = f.service.InviteMember(ctx, ownerID, f.team.ID, &InviteMemberRequest{User: "carol"})
+	assert.True(t, pkgErrors.IsPermissionError(err))
+	_, err = f.service.UpdateTeam(ctx, ownerID, f.team.ID, &UpdateTeamRequest{})
+	assert.True(t, pkgErrors.IsPermissionError(err))
+
+	// 只有所有者可以恢复，恢复后成员恢复为活跃
+	_, err = f.service.RestoreTeam(ctx, aliceID, f.team.ID)
+	assert.True(t, pkgErrors.IsPermissionError(err))
+	info, err := f.service.RestoreTeam(ctx, ownerID, f.team.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.TeamStatusActive, info.Status)
+	assert.Nil(t, info.PurgeAt)
+	require.NoError(t, f.service.RemoveFile(ctx, bobID, f.team.ID, 12))
+	_, err = f.service.RestoreTeam(ctx, ownerID, f.team.ID)
+	assert.True(t, pkgErrors.IsPermissionError(err), "未删除的团队不能恢复")
+
+	// 超过恢复期限后不能恢复，由维护任务彻底删除
+	require.NoError(t, f.service.DeleteTeam(ctx, ownerID, f.team.ID))
+	purged, err := f.service.PurgeArchived(ctx, 10)
+	require.NoError(t, err)
+	assert.Zero(t, purged)
+	now = now.Add(DefaultTeamRestoreWindow)
+	_, err = f.service.RestoreTeam(ctx, ownerID, f.team.ID)
+	assert.True(t, pkgErrors.IsPermissionError(err))
+	purged, err = f.service.PurgeArchived(ctx, 10)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, purged)
+	assert.NotContains(t, f.teams.teams, f.team.ID)
+	_, err = f.service.GetTeam(ctx, bobID, f.team.ID)
+	assert.True(t, pkgErrors.IsNotFoundError(err))
+}
@@ -0,0 +1,117 @@
+package usage
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"cloudpan/internal/pkg/config"
+	"cloudpan/internal/repository/models"
+)
+
+// UsageService API用量统计服务接口
+//
+// 每个请求结束时由中间件调用RecordRequest在内存中计数，Flush定期将计数
+// 按 用户+日期+API密钥+接口 累加写入日汇总表；报表合并已写入的日汇总和尚未写入的内存计数。
+// 配置了计量套餐角色时，只有持有其中任一角色的用户可以查看和导出报表
+//
+// 使用示例：
+//
+//	service := NewUsageService(usageRepo, userRepo, OptionsFromConfig(cfg), logger)
+//	service.RecordRequest(userID, 0, "GET /api/v1/files/:id/download", 200, 0, 1024)
+//	err := service.Flush(ctx)
+//	report, err := service.GetReport(ctx, userID, from, to)
+//	err = service.ExportCSV(ctx, userID, from, to, w)
+type UsageService interface {
+	RecordRequest(userID, apiKeyID uint, endpoint string, status int, bytesIn, bytesOut int64)
+	Flush(ctx context.Context) error
+	GetReport(ctx context.Context, userID uint, from, to time.Time) (*Report, error)
+	ExportCSV(ctx context.Context, userID uint, from, to time.Time, w io.Writer) error
+}
+
+// UsageStore 日汇总读写，由API用量仓储实现
+type UsageStore interface {
+	AddDaily(ctx context.Context, rows []*models.APIUsageDaily) error
+	ListDaily(ctx context.Context, userID uint, from, to string) ([]*models.APIUsageDaily, error)
+}
+
+// RoleReader 读取用户有效角色，由用户仓储实现
+type RoleReader interface {
+	ListActiveRoleNames(ctx context.Context, userID uint) ([]string, error)
+}
+
+// Options API用量统计选项
+type Options struct {
+	MeteredRoles  []string // 计量套餐对应的角色，为空时所有用户可查看报表
+	MaxReportDays int      // 单次查询或导出的最大天数
+	TopEndpoints  int      // 报表中列出的调用最多的接口数
+}
+
+// OptionsFromConfig 从API用量配置生成选项
+func OptionsFromConfig(cfg config.APIUsageConfig) Options {
+	return Options{
+		MeteredRoles:  cfg.MeteredRoles,
+		MaxReportDays: cfg.MaxReportDays,
+		TopEndpoints:  cfg.TopEndpoints,
+	}
+}
+
+// DateLayout 报表和CSV中的日期格式，日期按UTC划分
+const DateLayout = "2006-01-02"
+
+// Totals 用量合计
+type Totals struct {
+	Requests int64 `json:"requests"`  // 请求数
+	Errors   int64 `json:"errors"`    // 失败请求数(状态码>=400)
+	BytesIn  int64 `json:"bytes_in"`  // 请求流量
+	BytesOut int64 `json:"bytes_out"` // 响应流量
+}
+
+// DailyUsage 单日用量
+type DailyUsage struct {
+	Date string `json:"date"` // 日期(YYYY-MM-DD)
+	Totals
+}
+
+// EndpointUsage 单个接口的用量
+type EndpointUsage struct {
+	Endpoint string `json:"endpoint"` // 接口(方法+路由模板)
+	Totals
+}
+
+// APIKeyUsage 单个API密钥的用量
+type APIKeyUsage struct {
+	APIKeyID uint `json:"api_key_id"` // API密钥ID，0表示登录会话
+	Totals
+}
+
+// Report API用量报表
+type Report struct {
+	From         string          `json:"from"`          // 起始日期(包含)
+	To           string          `json:"to"`            // 结束日期(包含)
+	Totals       Totals          `json:"totals"`        // 范围内合计
+	Daily        []DailyUsage    `json:"daily"`         // 按日用量，没有请求的日期计为0
+	TopEndpoints []EndpointUsage `json:"top_endpoints"` // 请求数最多的接口
+	APIKeys      []APIKeyUsage   `json:"api_keys"`      // 按API密钥用量
+	GeneratedAt  time.Time       `json:"generated_at"`  // 生成时间
+}
+
+var (
+	defaultMu      sync.RWMutex
+	defaultService UsageService
+)
+
+// SetDefault 设置全局API用量统计服务，启动时由main调用
+func SetDefault(service UsageService) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultService = service
+}
+
+// Default 返回全局API用量统计服务，未启用时返回nil
+func Default() UsageService {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultService
+}
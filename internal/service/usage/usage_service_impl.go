@@ -0,0 +1,341 @@
+package usage
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/repository/models"
+)
+
+// 配置缺失时使用的内置默认值
+const (
+	defaultMaxReportDays = 366
+	defaultTopEndpoints  = 10
+)
+
+// counterKey 内存计数键
+type counterKey struct {
+	userID   uint
+	apiKeyID uint
+	date     string
+	endpoint string
+}
+
+// usageService API用量统计服务实现
+type usageService struct {
+	store    UsageStore
+	roleRepo RoleReader
+	options  Options
+	logger   *zap.Logger
+	now      func() time.Time
+
+	mu       sync.Mutex
+	counters map[counterKey]*Totals
+}
+
+// NewUsageService 创建API用量统计服务实例
+func NewUsageService(store UsageStore, roleRepo RoleReader, options Options, logger *zap.Logger) UsageService {
+	if options.MaxReportDays <= 0 {
+		options.MaxReportDays = defaultMaxReportDays
+	}
+	if options.TopEndpoints <= 0 {
+		options.TopEndpoints = defaultTopEndpoints
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &usageService{
+		store:    store,
+		roleRepo: roleRepo,
+		options:  options,
+		logger:   logger,
+		now:      time.Now,
+		counters: make(map[counterKey]*Totals),
+	}
+}
+
+// RecordRequest 在内存中记录一次请求，未登录的请求不计数
+func (s *usageService) RecordRequest(userID, apiKeyID uint, endpoint string, status int, bytesIn, bytesOut int64) {
+	if userID == 0 || endpoint == "" {
+		return
+	}
+
+	key := counterKey{
+		userID:   userID,
+		apiKeyID: apiKeyID,
+		date:     s.now().UTC().Format(DateLayout),
+		endpoint: endpoint,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	totals, ok := s.counters[key]
+	if !ok {
+		totals = &Totals{}
+		s.counters[key] = totals
+	}
+	totals.Requests++
+	if status >= 400 {
+		totals.Errors++
+	}
+	totals.BytesIn += max(bytesIn, 0)
+	totals.BytesOut += max(bytesOut, 0)
+}
+
+// Flush 将内存计数累加写入日汇总表
+//
+// 写入失败时计数放回内存，下次写入时重试，不会丢失也不会重复累加
+func (s *usageService) Flush(ctx context.Context) error {
+	s.mu.Lock()
+	pending := s.counters
+	s.counters = make(map[counterKey]*Totals)
+	s.mu.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	rows := make([]*models.APIUsageDaily, 0, len(pending))
+	for key, totals := range pending {
+		rows = append(rows, newDailyRow(key, totals))
+	}
+
+	if err := s.store.AddDaily(ctx, rows); err != nil {
+		s.mu.Lock()
+		for key, totals := range pending {
+			if current, ok := s.counters[key]; ok {
+				current.add(*totals)
+			} else {
+				s.counters[key] = totals
+			}
+		}
+		s.mu.Unlock()
+		return fmt.Errorf("写入API用量失败: %w", err)
+	}
+
+	s.logger.Debug("API usage flushed", zap.Int("rows", len(rows)))
+	return nil
+}
+
+// GetReport 生成用户在日期范围内(包含首尾)的API用量报表
+func (s *usageService) GetReport(ctx context.Context, userID uint, from, to time.Time) (*Report, error) {
+	rows, from, to, err := s.collect(ctx, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		From:        from.Format(DateLayout),
+		To:          to.Format(DateLayout),
+		GeneratedAt: s.now(),
+	}
+
+	daily := make(map[string]*Totals)
+	endpoints := make(map[string]*Totals)
+	apiKeys := make(map[uint]*Totals)
+	for _, row := range rows {
+		totals := rowTotals(row)
+		report.Totals.add(totals)
+		accumulate(daily, row.Date, totals)
+		accumulate(endpoints, row.Endpoint, totals)
+		accumulate(apiKeys, row.APIKeyID, totals)
+	}
+
+	report.Daily = make([]DailyUsage, 0, int(to.Sub(from).Hours()/24)+1)
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		date := day.Format(DateLayout)
+		usage := DailyUsage{Date: date}
+		if totals, ok := daily[date]; ok {
+			usage.Totals = *totals
+		}
+		report.Daily = append(report.Daily, usage)
+	}
+
+	report.TopEndpoints = make([]EndpointUsage, 0, len(endpoints))
+	for endpoint, totals := range endpoints {
+		report.TopEndpoints = append(report.TopEndpoints, EndpointUsage{Endpoint: endpoint, Totals: *totals})
+	}
+	sort.Slice(report.TopEndpoints, func(i, j int) bool {
+		a, b := report.TopEndpoints[i], report.TopEndpoints[j]
+		if a.Requests != b.Requests {
+			return a.Requests > b.Requests
+		}
+		return a.Endpoint < b.Endpoint
+	})
+	if len(report.TopEndpoints) > s.options.TopEndpoints {
+		report.TopEndpoints = report.TopEndpoints[:s.options.TopEndpoints]
+	}
+
+	report.APIKeys = make([]APIKeyUsage, 0, len(apiKeys))
+	for apiKeyID, totals := range apiKeys {
+		report.APIKeys = append(report.APIKeys, APIKeyUsage{APIKeyID: apiKeyID, Totals: *totals})
+	}
+	sort.Slice(report.APIKeys, func(i, j int) bool {
+		return report.APIKeys[i].APIKeyID < report.APIKeys[j].APIKeyID
+	})
+
+	return report, nil
+}
+
+// ExportCSV 以CSV格式导出用户在日期范围内的日汇总明细
+//
+// 每行一个 日期+API密钥+接口 的组合，按日期、API密钥、接口排序
+func (s *usageService) ExportCSV(ctx context.Context, userID uint, from, to time.Time, w io.Writer) error {
+	rows, _, _, err := s.collect(ctx, userID, from, to)
+	if err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"date", "api_key_id", "endpoint", "requests", "errors", "bytes_in", "bytes_out"}); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		record := []string{
+			row.Date,
+			strconv.FormatUint(uint64(row.APIKeyID), 10),
+			row.Endpoint,
+			strconv.FormatInt(row.Requests, 10),
+			strconv.FormatInt(row.Errors, 10),
+			strconv.FormatInt(row.BytesIn, 10),
+			strconv.FormatInt(row.BytesOut, 10),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// collect 校验权限和日期范围，返回合并了内存计数的日汇总记录
+func (s *usageService) collect(ctx context.Context, userID uint, from, to time.Time) ([]*models.APIUsageDaily, time.Time, time.Time, error) {
+	if userID == 0 {
+		return nil, from, to, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "用户ID不能为空")
+	}
+	from, to = truncateDay(from), truncateDay(to)
+	if from.After(to) {
+		return nil, from, to, pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "起始日期不能晚于结束日期")
+	}
+	if days := int(to.Sub(from).Hours()/24) + 1; days > s.options.MaxReportDays {
+		return nil, from, to, pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "查询范围不能超过%d天", s.options.MaxReportDays)
+	}
+	if err := s.checkMetered(ctx, userID); err != nil {
+		return nil, from, to, err
+	}
+
+	fromDate, toDate := from.Format(DateLayout), to.Format(DateLayout)
+	stored, err := s.store.ListDaily(ctx, userID, fromDate, toDate)
+	if err != nil {
+		return nil, from, to, fmt.Errorf("查询API用量失败: %w", err)
+	}
+
+	merged := make(map[counterKey]*models.APIUsageDaily, len(stored))
+	for _, row := range stored {
+		merged[counterKey{userID: userID, apiKeyID: row.APIKeyID, date: row.Date, endpoint: row.Endpoint}] = row
+	}
+
+	s.mu.Lock()
+	for key, totals := range s.counters {
+		if key.userID != userID || key.date < fromDate || key.date > toDate {
+			continue
+		}
+		if row, ok := merged[key]; ok {
+			row.Requests += totals.Requests
+			row.Errors += totals.Errors
+			row.BytesIn += totals.BytesIn
+			row.BytesOut += totals.BytesOut
+		} else {
+			merged[key] = newDailyRow(key, totals)
+		}
+	}
+	s.mu.Unlock()
+
+	rows := make([]*models.APIUsageDaily, 0, len(merged))
+	for _, row := range merged {
+		rows = append(rows, row)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		if a.Date != b.Date {
+			return a.Date < b.Date
+		}
+		if a.APIKeyID != b.APIKeyID {
+			return a.APIKeyID < b.APIKeyID
+		}
+		return a.Endpoint < b.Endpoint
+	})
+	return rows, from, to, nil
+}
+
+// checkMetered 检查用户是否使用计量套餐，未配置计量套餐角色时所有用户均可查看
+func (s *usageService) checkMetered(ctx context.Context, userID uint) error {
+	if len(s.options.MeteredRoles) == 0 {
+		return nil
+	}
+
+	roles, err := s.roleRepo.ListActiveRoleNames(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("获取用户角色失败: %w", err)
+	}
+	for _, role := range roles {
+		if slices.Contains(s.options.MeteredRoles, role) {
+			return nil
+		}
+	}
+	return pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, "当前套餐不提供API用量报表")
+}
+
+// add 累加用量
+func (t *Totals) add(other Totals) {
+	t.Requests += other.Requests
+	t.Errors += other.Errors
+	t.BytesIn += other.BytesIn
+	t.BytesOut += other.BytesOut
+}
+
+// accumulate 将用量累加到分组
+func accumulate[K comparable](groups map[K]*Totals, key K, totals Totals) {
+	group, ok := groups[key]
+	if !ok {
+		group = &Totals{}
+		groups[key] = group
+	}
+	group.add(totals)
+}
+
+// rowTotals 取出日汇总记录的用量
+func rowTotals(row *models.APIUsageDaily) Totals {
+	return Totals{Requests: row.Requests, Errors: row.Errors, BytesIn: row.BytesIn, BytesOut: row.BytesOut}
+}
+
+// newDailyRow 由内存计数生成日汇总记录
+func newDailyRow(key counterKey, totals *Totals) *models.APIUsageDaily {
+	return &models.APIUsageDaily{
+		UserID:   key.userID,
+		Date:     key.date,
+		APIKeyID: key.apiKeyID,
+		Endpoint: key.endpoint,
+		Requests: totals.Requests,
+		Errors:   totals.Errors,
+		BytesIn:  totals.BytesIn,
+		BytesOut: totals.BytesOut,
+	}
+}
+
+// truncateDay 取UTC日期的零点
+func truncateDay(t time.Time) time.Time {
+	year, month, day := t.UTC().Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,190 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/repository/models"
+)
+
+// memoryUsageStore 内存日汇总仓储，按唯一键累加
+type memoryUsageStore struct {
+	rows    map[counterKey]*models.APIUsageDaily
+	failAdd error
+}
+
+func newMemoryUsageStore() *memoryUsageStore {
+	return &memoryUsageStore{rows: map[counterKey]*models.APIUsageDaily{}}
+}
+
+func (m *memoryUsageStore) AddDaily(_ context.Context, rows []*models.APIUsageDaily) error {
+	if m.failAdd != nil {
+		return m.failAdd
+	}
+	for _, row := range rows {
+		key := counterKey{userID: row.UserID, apiKeyID: row.APIKeyID, date: row.Date, endpoint: row.Endpoint}
+		if existing, ok := m.rows[key]; ok {
+			existing.Requests += row.Requests
+			existing.Errors += row.Errors
+			existing.BytesIn += row.BytesIn
+			existing.BytesOut += row.BytesOut
+			continue
+		}
+		copied := *row
+		m.rows[key] = &copied
+	}
+	return nil
+}
+
+func (m *memoryUsageStore) ListDaily(_ context.Context, userID uint, from, to string) ([]*models.APIUsageDaily, error) {
+	var rows []*models.APIUsageDaily
+	for _, row := range m.rows {
+		if row.UserID == userID && row.Date >= from && row.Date <= to {
+			copied := *row
+			rows = append(rows, &copied)
+		}
+	}
+	return rows, nil
+}
+
+// stubRoleReader 固定角色
+type stubRoleReader map[uint][]string
+
+func (s stubRoleReader) ListActiveRoleNames(_ context.Context, userID uint) ([]string, error) {
+	return s[userID], nil
+}
+
+func newTestUsageService(store UsageStore, roles RoleReader, options Options, now *time.Time) *usageService {
+	service := NewUsageService(store, roles, options, nil).(*usageService)
+	service.now = func() time.Time { return *now }
+	return service
+}
+
+func day(value string) time.Time {
+	t, _ := time.Parse(DateLayout, value)
+	return t
+}
+
+func TestUsageService_FlushAccumulatesDaily(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryUsageStore()
+	now := time.Date(2024, 3, 1, 23, 30, 0, 0, time.UTC)
+	service := newTestUsageService(store, nil, Options{}, &now)
+
+	service.RecordRequest(7, 0, "GET /api/v1/files/:id/download", 200, 0, 100)
+	service.RecordRequest(7, 0, "GET /api/v1/files/:id/download", 404, 0, 20)
+	service.RecordRequest(0, 0, "GET /api/v1/version", 200, 0, 10)
+	require.NoError(t, service.Flush(ctx))
+
+	service.RecordRequest(7, 0, "GET /api/v1/files/:id/download", 200, 0, 100)
+	require.NoError(t, service.Flush(ctx))
+
+	require.Len(t, store.rows, 1, "未登录的请求不计数")
+	row := store.rows[counterKey{userID: 7, date: "2024-03-01", endpoint: "GET /api/v1/files/:id/download"}]
+	require.NotNil(t, row)
+	assert.Equal(t, int64(3), row.Requests)
+	assert.Equal(t, int64(1), row.Errors)
+	assert.Equal(t, int64(220), row.BytesOut)
+
+	// 跨过UTC零点后计入新的一天
+	now = now.Add(time.Hour)
+	service.RecordRequest(7, 0, "GET /api/v1/files/:id/download", 200, 0, 1)
+	require.NoError(t, service.Flush(ctx))
+	assert.Len(t, store.rows, 2)
+}
+
+func TestUsageService_FlushFailureKeepsCounters(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryUsageStore()
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	service := newTestUsageService(store, nil, Options{}, &now)
+
+	service.RecordRequest(7, 0, "POST /api/v1/files/uploads", 200, 50, 0)
+	store.failAdd = errors.New("db down")
+	assert.Error(t, service.Flush(ctx))
+
+	service.RecordRequest(7, 0, "POST /api/v1/files/uploads", 200, 50, 0)
+	store.failAdd = nil
+	require.NoError(t, service.Flush(ctx))
+
+	row := store.rows[counterKey{userID: 7, date: "2024-03-01", endpoint: "POST /api/v1/files/uploads"}]
+	require.NotNil(t, row)
+	assert.Equal(t, int64(2), row.Requests)
+	assert.Equal(t, int64(100), row.BytesIn)
+}
+
+func TestUsageService_GetReport(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryUsageStore()
+	now := time.Date(2024, 3, 2, 12, 0, 0, 0, time.UTC)
+	service := newTestUsageService(store, nil, Options{TopEndpoints: 1}, &now)
+
+	require.NoError(t, store.AddDaily(ctx, []*models.APIUsageDaily{
+		{UserID: 7, Date: "2024-03-01", Endpoint: "GET /api/v1/files/:id/download", Requests: 5, BytesOut: 500},
+		{UserID: 7, Date: "2024-03-01", APIKeyID: 3, Endpoint: "GET /api/v1/limits", Requests: 2},
+		{UserID: 8, Date: "2024-03-01", Endpoint: "GET /api/v1/limits", Requests: 9},
+	}))
+	// 尚未写入的内存计数同样计入报表
+	service.RecordRequest(7, 3, "GET /api/v1/limits", 500, 0, 0)
+
+	report, err := service.GetReport(ctx, 7, day("2024-03-01"), day("2024-03-03"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "2024-03-01", report.From)
+	assert.Equal(t, "2024-03-03", report.To)
+	assert.Equal(t, Totals{Requests: 8, Errors: 1, BytesOut: 500}, report.Totals)
+	require.Len(t, report.Daily, 3, "没有请求的日期计为0")
+	assert.Equal(t, int64(7), report.Daily[0].Requests)
+	assert.Equal(t, int64(1), report.Daily[1].Requests)
+	assert.Zero(t, report.Daily[2].Requests)
+	require.Len(t, report.TopEndpoints, 1)
+	assert.Equal(t, "GET /api/v1/files/:id/download", report.TopEndpoints[0].Endpoint)
+	assert.Equal(t, []APIKeyUsage{
+		{APIKeyID: 0, Totals: Totals{Requests: 5, BytesOut: 500}},
+		{APIKeyID: 3, Totals: Totals{Requests: 3, Errors: 1}},
+	}, report.APIKeys)
+}
+
+func TestUsageService_ExportCSV(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryUsageStore()
+	now := time.Date(2024, 3, 2, 12, 0, 0, 0, time.UTC)
+	service := newTestUsageService(store, nil, Options{}, &now)
+
+	require.NoError(t, store.AddDaily(ctx, []*models.APIUsageDaily{
+		{UserID: 7, Date: "2024-03-02", Endpoint: "GET /api/v1/limits", Requests: 1, BytesOut: 10},
+		{UserID: 7, Date: "2024-03-01", Endpoint: "GET /api/v1/files/:id/download", Requests: 2, Errors: 1, BytesOut: 30},
+	}))
+
+	var out strings.Builder
+	require.NoError(t, service.ExportCSV(ctx, 7, day("2024-03-01"), day("2024-03-02"), &out))
+	assert.Equal(t, "date,api_key_id,endpoint,requests,errors,bytes_in,bytes_out\n"+
+		"2024-03-01,0,GET /api/v1/files/:id/download,2,1,0,30\n"+
+		"2024-03-02,0,GET /api/v1/limits,1,0,0,10\n", out.String())
+}
+
+func TestUsageService_Access(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 2, 12, 0, 0, 0, time.UTC)
+	roles := stubRoleReader{7: {"user", "metered"}, 8: {"user"}}
+	service := newTestUsageService(newMemoryUsageStore(), roles, Options{MeteredRoles: []string{"metered"}, MaxReportDays: 31}, &now)
+
+	_, err := service.GetReport(ctx, 7, day("2024-03-01"), day("2024-03-02"))
+	assert.NoError(t, err)
+
+	_, err = service.GetReport(ctx, 8, day("2024-03-01"), day("2024-03-02"))
+	assert.True(t, pkgErrors.IsPermissionError(err), "非计量套餐用户不能查看报表")
+
+	_, err = service.GetReport(ctx, 7, day("2024-03-02"), day("2024-03-01"))
+	assert.ErrorIs(t, err, pkgErrors.ErrInvalidInput)
+
+	_, err = service.GetReport(ctx, 7, day("2024-01-01"), day("2024-03-01"))
+	assert.ErrorIs(t, err, pkgErrors.ErrInvalidInput, "超过最大查询天数")
+}
@@ -0,0 +1,259 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/cache"
+	basemodels "cloudpan/internal/pkg/database/models"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+	userrepo "cloudpan/internal/repository/user"
+	"cloudpan/internal/service/file"
+)
+
+// 账户合并参数
+const (
+	MergeTokenTTL    = 10 * time.Minute // 合并令牌有效期
+	mergeTokenLength = 32               // 合并令牌长度(十六进制字符)
+	mergeReason      = "账户合并"           // 文件转移记录中的原因
+)
+
+// 已合并账户 profile 中的字段
+const (
+	ProfileKeyMergedInto = "merged_into" // 合并到的账户ID
+	ProfileKeyMergedAt   = "merged_at"   // 合并时间(RFC 3339)
+)
+
+// AccountMergeService 账户合并服务接口
+//
+// 同一用户用不同方式(如邮箱注册和单点登录)创建了两个账户时，把其中一个(来源账户)合并到另一个(目标账户)：
+// 1. 登录来源账户申请合并令牌，证明持有来源账户；令牌 MergeTokenTTL 内有效，只能使用一次
+// 2. 登录目标账户提交合并令牌，证明同时持有两个账户后执行合并
+//
+// 合并时先检查目标账户的剩余空间能容纳来源账户的已用空间，再把来源账户根目录下的全部文件(分享保持有效)
+// 转到目标账户根目录下"<来源用户名> 的文件"文件夹中，然后在一个事务中转移团队成员身份、团队所有权和单点登录身份关联，
+// 并把来源账户停用为已合并(profile中记录合并到的账户)，最后吊销来源账户已签发的令牌。
+// 来源账户的邮箱和用户名仍由已合并的账户占用，回收站中的文件不转移。
+// 有文件转移失败时不停用来源账户，已转移的文件保留在目标账户中，重新申请令牌后可以再次合并
+//
+// 使用示例：
+//
+//	service := NewAccountMergeService(userRepo, userrepo.NewAccountMergeRepository(db), transferService, quotaService, cache.DefaultMergeTokenStore(), cache.DefaultTokenStore(), logger)
+//	token, err := service.CreateToken(ctx, sourceUserID)
+//	result, err := service.Merge(ctx, targetUserID, token.Token)
+type AccountMergeService interface {
+	CreateToken(ctx context.Context, userID uint) (*cache.MergeToken, error)
+	Merge(ctx context.Context, userID uint, token string) (*MergeResult, error)
+}
+
+// MergeFileTransfer 转移来源账户的全部文件，由 file.TransferService 实现
+type MergeFileTransfer interface {
+	ForceTransfer(ctx context.Context, adminID uint, req *file.ForceTransferRequest) (*file.ForceTransferResult, error)
+}
+
+// MergeResult 账户合并结果
+type MergeResult struct {
+	SourceID       uint                         `json:"source_id"`        // 已合并的来源账户ID
+	SourceUsername string                       `json:"source_username"`  // 来源账户用户名
+	SourceEmail    string                       `json:"source_email"`     // 来源账户邮箱
+	Folder         *models.File                 `json:"folder,omitempty"` // 在目标账户根目录创建的文件夹，来源账户没有文件时为空
+	Files          int                          `json:"files"`            // 转移的根目录文件和文件夹数
+	StorageUsed    int64                        `json:"storage_used"`     // 合并前来源账户的已用空间
+	Counts         *userrepo.AccountMergeCounts `json:"counts"`           // 转移的团队和身份关联数
+}
+
+// accountMergeService 账户合并服务实现
+type accountMergeService struct {
+	users      StorageUserReader
+	repo       userrepo.AccountMergeRepository
+	files      MergeFileTransfer
+	quota      StorageQuotaService
+	tokens     cache.MergeTokenStore
+	tokenStore cache.TokenStore
+	logger     *zap.Logger
+	now        func() time.Time
+}
+
+// NewAccountMergeService 创建账户合并服务
+//
+// quota 为nil时按目标账户的配额和已用空间检查，不计进行中的上传预留；tokenStore 为nil时不吊销来源账户的令牌
+func NewAccountMergeService(users StorageUserReader, repo userrepo.AccountMergeRepository, files MergeFileTransfer, quota StorageQuotaService,
+	tokens cache.MergeTokenStore, tokenStore cache.TokenStore, logger *zap.Logger) AccountMergeService {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &accountMergeService{
+		users:      users,
+		repo:       repo,
+		files:      files,
+		quota:      quota,
+		tokens:     tokens,
+		tokenStore: tokenStore,
+		logger:     logger,
+		now:        time.Now,
+	}
+}
+
+// CreateToken 为要合并掉的账户申请合并令牌
+func (s *accountMergeService) CreateToken(ctx context.Context, userID uint) (*cache.MergeToken, error) {
+	if userID == 0 {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "用户ID不能为空")
+	}
+	if _, err := s.activeAccount(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	value, err := utils.GenerateHex(mergeTokenLength)
+	if err != nil {
+		return nil, pkgErrors.WrapError(err, "生成合并令牌失败")
+	}
+	token := &cache.MergeToken{Token: value, UserID: uint64(userID), ExpiresAt: s.now().Add(MergeTokenTTL)}
+	if err := s.tokens.Save(ctx, token); err != nil {
+		return nil, pkgErrors.WrapError(err, "保存合并令牌失败")
+	}
+
+	s.logger.Info("Account merge token issued", zap.Uint("user_id", userID))
+	return token, nil
+}
+
+// Merge 把合并令牌对应的来源账户合并到 userID 账户
+func (s *accountMergeService) Merge(ctx context.Context, userID uint, token string) (*MergeResult, error) {
+	if userID == 0 || token == "" {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "用户ID和合并令牌不能为空")
+	}
+	// 先读取令牌完成全部校验，可修正后重试的失败(如空间不足)不消耗令牌
+	pending, err := s.readToken(ctx, s.tokens.Get, token)
+	if err != nil {
+		return nil, err
+	}
+	sourceID := uint(pending.UserID)
+	if sourceID == userID {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "请登录要保留的账户后提交合并令牌")
+	}
+
+	target, err := s.activeAccount(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	source, err := s.activeAccount(ctx, sourceID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkQuota(ctx, target, source.StorageUsed); err != nil {
+		return nil, err
+	}
+
+	// 校验通过后再消耗令牌，并发提交时只有一个请求继续合并
+	taken, err := s.readToken(ctx, s.tokens.Take, token)
+	if err != nil {
+		return nil, err
+	}
+	if taken.UserID != pending.UserID {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "合并令牌无效或已过期，请在要合并的账户中重新申请")
+	}
+
+	transferred, err := s.files.ForceTransfer(ctx, userID, &file.ForceTransferRequest{
+		FromUserID: sourceID,
+		ToUserID:   userID,
+		ShareMode:  models.TransferShareKeep,
+		Reason:     mergeReason,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if transferred.Failed > 0 {
+		s.logger.Warn("Account merge stopped after file transfer failures",
+			zap.Uint("source_id", sourceID),
+			zap.Uint("target_id", userID),
+			zap.Int("completed", transferred.Completed),
+			zap.Int("failed", transferred.Failed))
+		return nil, pkgErrors.WrapErrorf(pkgErrors.ErrOperationNotAllowed,
+			"%d 个文件转移失败，要合并的账户未停用，请重新申请合并令牌后重试", transferred.Failed)
+	}
+
+	now := s.now()
+	profile := basemodels.JSONMap{}
+	if source.Profile != nil {
+		for key, value := range *source.Profile {
+			profile[key] = value
+		}
+	}
+	profile[ProfileKeyMergedInto] = userID
+	profile[ProfileKeyMergedAt] = now.UTC().Format(time.RFC3339)
+	counts, err := s.repo.MergeInto(ctx, sourceID, userID, &profile, now)
+	if err != nil {
+		if errors.Is(err, userrepo.ErrMergeSourceChanged) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "要合并的账户状态已变化")
+		}
+		return nil, pkgErrors.WrapError(err, "合并账户失败")
+	}
+
+	if s.tokenStore != nil {
+		if err := s.tokenStore.RevokeUserTokens(ctx, uint64(sourceID), now); err != nil {
+			s.logger.Error("Failed to revoke merged account tokens", zap.Uint("user_id", sourceID), zap.Error(err))
+		}
+	}
+
+	s.logger.Info("Accounts merged",
+		zap.Uint("source_id", sourceID),
+		zap.Uint("target_id", userID),
+		zap.Int("files", transferred.Completed),
+		zap.Int64("teams", counts.Teams),
+		zap.Int64("identities", counts.Identities))
+	return &MergeResult{
+		SourceID:       sourceID,
+		SourceUsername: source.Username,
+		SourceEmail:    source.Email,
+		Folder:         transferred.Folder,
+		Files:          transferred.Completed,
+		StorageUsed:    source.StorageUsed,
+		Counts:         counts,
+	}, nil
+}
+
+// readToken 用 read(Get或Take)读取合并令牌，令牌不存在或已过期时返回参数错误
+func (s *accountMergeService) readToken(ctx context.Context, read func(context.Context, string) (*cache.MergeToken, error), token string) (*cache.MergeToken, error) {
+	pending, err := read(ctx, token)
+	if err != nil {
+		if errors.Is(err, cache.ErrMergeTokenNotFound) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "合并令牌无效或已过期，请在要合并的账户中重新申请")
+		}
+		return nil, pkgErrors.WrapError(err, "读取合并令牌失败")
+	}
+	return pending, nil
+}
+
+// checkQuota 检查目标账户的剩余空间能容纳来源账户的已用空间
+func (s *accountMergeService) checkQuota(ctx context.Context, target *models.User, size int64) error {
+	if size <= 0 {
+		return nil
+	}
+	if s.quota != nil {
+		return s.quota.Check(ctx, target.ID, size)
+	}
+	if usage := newStorageUsage(target, 0); !usage.fits(size) {
+		return &QuotaExceededError{Usage: usage, Required: size}
+	}
+	return nil
+}
+
+// activeAccount 获取正常状态的账户，合并的两个账户都必须可用
+func (s *accountMergeService) activeAccount(ctx context.Context, userID uint) (*models.User, error) {
+	account, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "账户不存在")
+		}
+		return nil, pkgErrors.WrapError(err, "获取用户失败")
+	}
+	if !account.IsActive() {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "账户当前不可合并")
+	}
+	return account, nil
+}
@@ -0,0 +1,159 @@
+package user
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"cloudpan/internal/pkg/cache"
+	basemodels "cloudpan/internal/pkg/database/models"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/repository/models"
+	userrepo "cloudpan/internal/repository/user"
+	"cloudpan/internal/service/file"
+)
+
+// memoryMergeRepository 记录合并调用，来源账户改为已删除
+type memoryMergeRepository struct {
+	store   *memoryReservationStore
+	merged  map[uint]uint
+	profile basemodels.JSONMap
+}
+
+func (r *memoryMergeRepository) MergeInto(_ context.Context, sourceID, targetID uint, profile *basemodels.JSONMap, _ time.Time) (*userrepo.AccountMergeCounts, error) {
+	source := r.store.users[sourceID]
+	if source.Status != "active" {
+		return nil, userrepo.ErrMergeSourceChanged
+	}
+	source.Status = "deleted"
+	r.merged[sourceID] = targetID
+	r.profile = *profile
+	return &userrepo.AccountMergeCounts{Teams: 2, Identities: 1}, nil
+}
+
+// stubMergeFiles 返回固定结果的文件转移
+type stubMergeFiles struct {
+	result   *file.ForceTransferResult
+	requests []*file.ForceTransferRequest
+}
+
+func (s *stubMergeFiles) ForceTransfer(_ context.Context, _ uint, req *file.ForceTransferRequest) (*file.ForceTransferResult, error) {
+	s.requests = append(s.requests, req)
+	return s.result, nil
+}
+
+func newTestAccountMergeService(users ...*models.User) (*accountMergeService, *memoryMergeRepository, *stubMergeFiles, *cache.MemoryTokenStore) {
+	store := newMemoryReservationStore(users...)
+	repo := &memoryMergeRepository{store: store, merged: make(map[uint]uint)}
+	files := &stubMergeFiles{result: &file.ForceTransferResult{Folder: &models.File{Name: "alice 的文件"}, Completed: 3}}
+	tokenStore := cache.NewMemoryTokenStore(time.Hour)
+	service := NewAccountMergeService(store, repo, files, nil, cache.NewMemoryMergeTokenStore(), tokenStore, nil).(*accountMergeService)
+	return service, repo, files, tokenStore
+}
+
+func mergeTestUser(id uint, username string, quota, used int64) *models.User {
+	account := &models.User{Username: username, Email: username + "@example.com", Status: "active", StorageQuota: quota, StorageUsed: used}
+	account.ID = id
+	return account
+}
+
+func TestAccountMergeService_Merge(t *testing.T) {
+	ctx := context.Background()
+	source := mergeTestUser(2, "alice", 1<<30, 300)
+	source.Profile = &basemodels.JSONMap{"tenant": "acme"}
+	service, repo, files, tokenStore := newTestAccountMergeService(mergeTestUser(1, "alice.wang", 1<<30, 100), source)
+
+	token, err := service.CreateToken(ctx, 2)
+	require.NoError(t, err)
+	assert.Len(t, token.Token, mergeTokenLength)
+
+	result, err := service.Merge(ctx, 1, token.Token)
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), result.SourceID)
+	assert.Equal(t, 3, result.Files)
+	assert.Equal(t, int64(300), result.StorageUsed)
+	assert.Equal(t, int64(2), result.Counts.Teams)
+
+	require.Len(t, files.requests, 1)
+	assert.Equal(t, &file.ForceTransferRequest{FromUserID: 2, ToUserID: 1, ShareMode: models.TransferShareKeep, Reason: mergeReason}, files.requests[0])
+	assert.Equal(t, uint(1), repo.merged[2])
+	assert.Equal(t, uint(1), repo.profile[ProfileKeyMergedInto])
+	assert.Equal(t, "acme", repo.profile["tenant"], "保留来源账户原有的配置")
+
+	revoked, err := tokenStore.RevokedBefore(ctx, 2)
+	require.NoError(t, err)
+	assert.False(t, revoked.IsZero(), "吊销来源账户的令牌")
+
+	// 令牌只能使用一次
+	_, err = service.Merge(ctx, 1, token.Token)
+	assert.ErrorIs(t, err, pkgErrors.ErrInvalidInput)
+	// 已合并的账户不能再申请令牌
+	_, err = service.CreateToken(ctx, 2)
+	assert.ErrorIs(t, err, pkgErrors.ErrOperationNotAllowed)
+}
+
+func TestAccountMergeService_MergeRejected(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("不能合并到同一个账户", func(t *testing.T) {
+		service, _, files, _ := newTestAccountMergeService(mergeTestUser(1, "alice", 0, 0))
+		token, err := service.CreateToken(ctx, 1)
+		require.NoError(t, err)
+
+		_, err = service.Merge(ctx, 1, token.Token)
+		assert.ErrorIs(t, err, pkgErrors.ErrInvalidInput)
+		assert.Empty(t, files.requests)
+	})
+
+	t.Run("目标账户空间不足时不转移文件", func(t *testing.T) {
+		service, repo, files, _ := newTestAccountMergeService(mergeTestUser(1, "alice.wang", 1000, 900), mergeTestUser(2, "alice", 1000, 200))
+		token, err := service.CreateToken(ctx, 2)
+		require.NoError(t, err)
+
+		_, err = service.Merge(ctx, 1, token.Token)
+		var quotaErr *QuotaExceededError
+		require.ErrorAs(t, err, &quotaErr)
+		assert.Equal(t, int64(200), quotaErr.Required)
+		assert.Equal(t, int64(100), quotaErr.Usage.Available)
+		assert.Empty(t, files.requests)
+		assert.Empty(t, repo.merged)
+	})
+
+	t.Run("空间不足时不消耗令牌，释放空间后可重试", func(t *testing.T) {
+		service, repo, files, _ := newTestAccountMergeService(mergeTestUser(1, "alice.wang", 1000, 900), mergeTestUser(2, "alice", 1000, 200))
+		token, err := service.CreateToken(ctx, 2)
+		require.NoError(t, err)
+
+		_, err = service.Merge(ctx, 1, token.Token)
+		var quotaErr *QuotaExceededError
+		require.ErrorAs(t, err, &quotaErr)
+
+		repo.store.users[1].StorageUsed = 500
+		result, err := service.Merge(ctx, 1, token.Token)
+		require.NoError(t, err)
+		assert.Equal(t, uint(2), result.SourceID)
+		assert.Len(t, files.requests, 1)
+		assert.Equal(t, uint(1), repo.merged[2])
+	})
+
+	t.Run("有文件转移失败时不停用来源账户", func(t *testing.T) {
+		service, repo, files, _ := newTestAccountMergeService(mergeTestUser(1, "alice.wang", 0, 0), mergeTestUser(2, "alice", 0, 0))
+		files.result = &file.ForceTransferResult{Completed: 1, Failed: 2}
+		token, err := service.CreateToken(ctx, 2)
+		require.NoError(t, err)
+
+		_, err = service.Merge(ctx, 1, token.Token)
+		assert.ErrorIs(t, err, pkgErrors.ErrOperationNotAllowed)
+		assert.Contains(t, err.Error(), "2 个文件转移失败")
+		assert.Empty(t, repo.merged)
+	})
+
+	t.Run("令牌无效", func(t *testing.T) {
+		service, _, _, _ := newTestAccountMergeService(mergeTestUser(1, "alice", 0, 0))
+		_, err := service.Merge(ctx, 1, "missing")
+		assert.ErrorIs(t, err, pkgErrors.ErrInvalidInput)
+	})
+}
@@ -0,0 +1,245 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"cloudpan/internal/pkg/config"
+	basemodels "cloudpan/internal/pkg/database/models"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+)
+
+// API密钥参数
+const (
+	apiKeyPrefix        = "cpk_"        // 密钥前缀，便于识别和密钥扫描工具检测泄露
+	apiKeyRandomLength  = 40            // 前缀之后的随机字符数
+	apiKeyDisplayLength = 12            // 列表中展示的密钥前缀长度
+	apiKeyNameMaxLength = 100           // 密钥名称最大长度(字符)
+	apiKeyTouchInterval = time.Minute   // 最后使用时间的最短更新间隔，避免每个请求都写数据库
+	defaultAPIKeyLimit  = 10            // 默认每个用户最多持有的密钥数
+	apiKeyInvalidReason = "API密钥无效或已过期" // 认证失败的原因，不区分密钥不存在、过期和账户停用
+)
+
+// APIKeyStore API密钥需要的数据访问，由 userrepo.APIKeyRepository 实现
+type APIKeyStore interface {
+	Create(ctx context.Context, key *models.APIKey, limit int) (bool, error)
+	ListByUser(ctx context.Context, userID uint) ([]*models.APIKey, error)
+	GetByHash(ctx context.Context, keyHash string) (*models.APIKey, error)
+	Delete(ctx context.Context, userID, id uint) (*models.APIKey, error)
+	TouchLastUsed(ctx context.Context, id uint, ip string, usedAt time.Time) error
+}
+
+// APIKeyOptions API密钥选项
+type APIKeyOptions struct {
+	MaxPerUser int           // 每个用户最多持有的密钥数，默认10
+	MaxTTL     time.Duration // 密钥最长有效期，为0时允许创建不过期的密钥
+}
+
+// APIKeyOptionsFromConfig 从配置生成API密钥选项
+func APIKeyOptionsFromConfig(cfg config.APIKeyConfig) APIKeyOptions {
+	return APIKeyOptions{
+		MaxPerUser: cfg.MaxPerUser,
+		MaxTTL:     cfg.MaxTTL,
+	}
+}
+
+// CreateAPIKeyRequest 创建API密钥请求
+type CreateAPIKeyRequest struct {
+	Name   string        // 密钥名称
+	Scopes []string      // 权限范围，取值见 models.APIKeyScopes
+	TTL    time.Duration // 有效期，为0表示不过期
+}
+
+// CreatedAPIKey 新创建的API密钥，明文只在创建时返回一次
+type CreatedAPIKey struct {
+	Key    string         // 密钥明文
+	APIKey *models.APIKey // 密钥记录
+}
+
+// APIKeyService 用户API密钥服务接口
+//
+// 密钥格式为 cpk_ 加40位随机字母数字，只保存SHA-256哈希；认证时检查密钥未过期且所有者账户正常，
+// 权限范围由认证中间件按请求检查。API密钥请求的角色固定为普通用户
+//
+// 使用示例：
+//
+//	service := NewAPIKeyService(userrepo.NewAPIKeyRepository(db), userrepo.NewUserRepository(db), APIKeyOptionsFromConfig(cfg), logger)
+//	created, err := service.Create(ctx, userID, &CreateAPIKeyRequest{Name: "backup", Scopes: []string{models.APIKeyScopeRead}})
+//	key, user, err := service.Authenticate(ctx, created.Key, ip)
+type APIKeyService interface {
+	Create(ctx context.Context, userID uint, req *CreateAPIKeyRequest) (*CreatedAPIKey, error)
+	List(ctx context.Context, userID uint) ([]*models.APIKey, error)
+	Revoke(ctx context.Context, userID, id uint) (*models.APIKey, error)
+	Authenticate(ctx context.Context, key, ip string) (*models.APIKey, *models.User, error)
+}
+
+// apiKeyService 用户API密钥服务实现
+type apiKeyService struct {
+	keys    APIKeyStore
+	users   StorageUserReader
+	options APIKeyOptions
+	logger  *zap.Logger
+	now     func() time.Time
+}
+
+// NewAPIKeyService 创建用户API密钥服务，未配置的选项使用默认值
+func NewAPIKeyService(keys APIKeyStore, users StorageUserReader, options APIKeyOptions, logger *zap.Logger) APIKeyService {
+	if options.MaxPerUser <= 0 {
+		options.MaxPerUser = defaultAPIKeyLimit
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &apiKeyService{
+		keys:    keys,
+		users:   users,
+		options: options,
+		logger:  logger,
+		now:     time.Now,
+	}
+}
+
+// Create 创建API密钥
+func (s *apiKeyService) Create(ctx context.Context, userID uint, req *CreateAPIKeyRequest) (*CreatedAPIKey, error) {
+	if userID == 0 || req == nil {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "用户ID和密钥信息不能为空")
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" || utf8.RuneCountInString(name) > apiKeyNameMaxLength {
+		return nil, pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "密钥名称不能为空且不能超过%d个字符", apiKeyNameMaxLength)
+	}
+	scopes, err := normalizeAPIKeyScopes(req.Scopes)
+	if err != nil {
+		return nil, err
+	}
+	if req.TTL < 0 {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "有效期不能为负数")
+	}
+	if maxTTL := s.options.MaxTTL; maxTTL > 0 && (req.TTL == 0 || req.TTL > maxTTL) {
+		return nil, pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "有效期不能超过%d天", int(maxTTL.Hours()/24))
+	}
+
+	random, err := utils.GenerateAlphanumeric(apiKeyRandomLength)
+	if err != nil {
+		return nil, pkgErrors.WrapError(err, "生成API密钥失败")
+	}
+	plain := apiKeyPrefix + random
+	key := &models.APIKey{
+		UserID:  userID,
+		Name:    name,
+		Prefix:  plain[:apiKeyDisplayLength],
+		KeyHash: basemodels.HashToken(plain),
+		Scopes:  strings.Join(scopes, ","),
+	}
+	if req.TTL > 0 {
+		expiresAt := s.now().Add(req.TTL)
+		key.ExpiresAt = &expiresAt
+	}
+
+	created, err := s.keys.Create(ctx, key, s.options.MaxPerUser)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "用户不存在")
+		}
+		return nil, err
+	}
+	if !created {
+		return nil, pkgErrors.WrapErrorf(pkgErrors.ErrOperationNotAllowed, "最多只能创建%d个API密钥，请先撤销不再使用的密钥", s.options.MaxPerUser)
+	}
+
+	s.logger.Info("API key created",
+		zap.Uint("user_id", userID),
+		zap.Uint("api_key_id", key.ID),
+		zap.String("prefix", key.Prefix),
+		zap.String("scopes", key.Scopes))
+	return &CreatedAPIKey{Key: plain, APIKey: key}, nil
+}
+
+// List 列出用户的API密钥
+func (s *apiKeyService) List(ctx context.Context, userID uint) ([]*models.APIKey, error) {
+	return s.keys.ListByUser(ctx, userID)
+}
+
+// Revoke 撤销用户的API密钥，返回撤销前的记录
+func (s *apiKeyService) Revoke(ctx context.Context, userID, id uint) (*models.APIKey, error) {
+	key, err := s.keys.Delete(ctx, userID, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "API密钥不存在")
+		}
+		return nil, err
+	}
+	s.logger.Info("API key revoked", zap.Uint("user_id", userID), zap.Uint("api_key_id", id))
+	return key, nil
+}
+
+// Authenticate 按密钥明文认证，返回密钥记录和所有者
+//
+// 密钥不存在、已过期或所有者账户不可用时返回 pkgErrors.ErrPermissionDenied；
+// 最后使用时间按 apiKeyTouchInterval 间隔更新，更新失败只记录日志
+func (s *apiKeyService) Authenticate(ctx context.Context, key, ip string) (*models.APIKey, *models.User, error) {
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return nil, nil, pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, apiKeyInvalidReason)
+	}
+	record, err := s.keys.GetByHash(ctx, basemodels.HashToken(key))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, apiKeyInvalidReason)
+		}
+		return nil, nil, fmt.Errorf("查询API密钥失败: %w", err)
+	}
+	now := s.now()
+	if record.IsExpired(now) {
+		return nil, nil, pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, apiKeyInvalidReason)
+	}
+
+	owner, err := s.users.GetByID(ctx, record.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, apiKeyInvalidReason)
+		}
+		return nil, nil, fmt.Errorf("获取用户失败: %w", err)
+	}
+	if !owner.IsActive() {
+		return nil, nil, pkgErrors.WrapError(pkgErrors.ErrPermissionDenied, apiKeyInvalidReason)
+	}
+
+	if record.LastUsedAt == nil || now.Sub(*record.LastUsedAt) >= apiKeyTouchInterval {
+		if err := s.keys.TouchLastUsed(ctx, record.ID, ip, now); err != nil {
+			s.logger.Warn("Failed to record API key usage", zap.Uint("api_key_id", record.ID), zap.Error(err))
+		} else {
+			record.LastUsedAt = &now
+			record.LastUsedIP = &ip
+		}
+	}
+	return record, owner, nil
+}
+
+// normalizeAPIKeyScopes 校验权限范围并去重，按 models.APIKeyScopes 的顺序返回
+func normalizeAPIKeyScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "至少需要一个权限范围")
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(models.APIKeyScopes, scope) {
+			return nil, pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "不支持的权限范围: %s，可选 %s", scope, strings.Join(models.APIKeyScopes, "/"))
+		}
+	}
+	normalized := make([]string, 0, len(scopes))
+	for _, scope := range models.APIKeyScopes {
+		if slices.Contains(scopes, scope) {
+			normalized = append(normalized, scope)
+		}
+	}
+	return normalized, nil
+}
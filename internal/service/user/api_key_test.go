@@ -0,0 +1,159 @@
+package user
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/repository/models"
+)
+
+// memoryAPIKeyStore 内存API密钥存储，行为与数据库仓库一致
+type memoryAPIKeyStore struct {
+	keys    map[uint]*models.APIKey
+	nextID  uint
+	touches int
+}
+
+func (s *memoryAPIKeyStore) Create(_ context.Context, key *models.APIKey, limit int) (bool, error) {
+	count := 0
+	for _, existing := range s.keys {
+		if existing.UserID == key.UserID {
+			count++
+		}
+	}
+	if limit > 0 && count >= limit {
+		return false, nil
+	}
+	s.nextID++
+	key.ID = s.nextID
+	copied := *key
+	s.keys[key.ID] = &copied
+	return true, nil
+}
+
+func (s *memoryAPIKeyStore) ListByUser(_ context.Context, userID uint) ([]*models.APIKey, error) {
+	var keys []*models.APIKey
+	for _, key := range s.keys {
+		if key.UserID == userID {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func (s *memoryAPIKeyStore) GetByHash(_ context.Context, keyHash string) (*models.APIKey, error) {
+	for _, key := range s.keys {
+		if key.KeyHash == keyHash {
+			copied := *key
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (s *memoryAPIKeyStore) Delete(_ context.Context, userID, id uint) (*models.APIKey, error) {
+	key, ok := s.keys[id]
+	if !ok || key.UserID != userID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	delete(s.keys, id)
+	return key, nil
+}
+
+func (s *memoryAPIKeyStore) TouchLastUsed(_ context.Context, id uint, ip string, usedAt time.Time) error {
+	s.touches++
+	s.keys[id].LastUsedAt = &usedAt
+	s.keys[id].LastUsedIP = &ip
+	return nil
+}
+
+func newTestAPIKeyService(options APIKeyOptions, users ...*models.User) (*apiKeyService, *memoryAPIKeyStore, *time.Time) {
+	store := &memoryAPIKeyStore{keys: make(map[uint]*models.APIKey)}
+	service := NewAPIKeyService(store, newMemoryReservationStore(users...), options, nil).(*apiKeyService)
+	now := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+	return service, store, &now
+}
+
+func TestAPIKeyService_CreateAndAuthenticate(t *testing.T) {
+	ctx := context.Background()
+	owner := &models.User{Email: "alice@example.com", Username: "alice", Status: "active"}
+	owner.ID = 1
+	service, store, now := newTestAPIKeyService(APIKeyOptions{}, owner)
+
+	created, err := service.Create(ctx, 1, &CreateAPIKeyRequest{Name: " backup ", Scopes: []string{"share", "read", "read"}, TTL: time.Hour})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(created.Key, "cpk_"))
+	assert.Len(t, created.Key, 44)
+	assert.Equal(t, "backup", created.APIKey.Name)
+	assert.Equal(t, "read,share", created.APIKey.Scopes, "去重并按固定顺序保存")
+	assert.Equal(t, created.Key[:12], created.APIKey.Prefix)
+	assert.NotContains(t, store.keys[created.APIKey.ID].KeyHash, created.Key, "只保存哈希")
+
+	key, account, err := service.Authenticate(ctx, created.Key, "10.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, created.APIKey.ID, key.ID)
+	assert.Equal(t, "alice", account.Username)
+	assert.True(t, key.HasScope(models.APIKeyScopeShare))
+	assert.False(t, key.HasScope(models.APIKeyScopeWrite))
+	assert.Equal(t, 1, store.touches)
+
+	// 间隔内重复使用不更新最后使用时间
+	_, _, err = service.Authenticate(ctx, created.Key, "10.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, store.touches)
+
+	for _, invalid := range []string{"", "cpk_unknown", strings.TrimPrefix(created.Key, "cpk_")} {
+		_, _, err = service.Authenticate(ctx, invalid, "10.0.0.1")
+		assert.ErrorIs(t, err, pkgErrors.ErrPermissionDenied, invalid)
+	}
+
+	*now = now.Add(time.Hour)
+	_, _, err = service.Authenticate(ctx, created.Key, "10.0.0.1")
+	assert.ErrorIs(t, err, pkgErrors.ErrPermissionDenied, "已过期")
+}
+
+func TestAPIKeyService_Rejected(t *testing.T) {
+	ctx := context.Background()
+	owner := &models.User{Username: "alice", Status: "active"}
+	owner.ID = 1
+	suspended := &models.User{Username: "bob", Status: "suspended"}
+	suspended.ID = 2
+	service, _, _ := newTestAPIKeyService(APIKeyOptions{MaxPerUser: 1, MaxTTL: 30 * 24 * time.Hour}, owner, suspended)
+
+	_, err := service.Create(ctx, 1, &CreateAPIKeyRequest{Name: "ci", Scopes: []string{"admin"}, TTL: time.Hour})
+	assert.ErrorIs(t, err, pkgErrors.ErrInvalidInput)
+	_, err = service.Create(ctx, 1, &CreateAPIKeyRequest{Name: "ci", Scopes: nil, TTL: time.Hour})
+	assert.ErrorIs(t, err, pkgErrors.ErrInvalidInput)
+	_, err = service.Create(ctx, 1, &CreateAPIKeyRequest{Name: "ci", Scopes: []string{"read"}})
+	assert.ErrorIs(t, err, pkgErrors.ErrInvalidInput, "配置了最长有效期时不能创建不过期的密钥")
+	_, err = service.Create(ctx, 1, &CreateAPIKeyRequest{Name: "ci", Scopes: []string{"read"}, TTL: 31 * 24 * time.Hour})
+	assert.ErrorIs(t, err, pkgErrors.ErrInvalidInput)
+
+	created, err := service.Create(ctx, 1, &CreateAPIKeyRequest{Name: "ci", Scopes: []string{"read"}, TTL: time.Hour})
+	require.NoError(t, err)
+	_, err = service.Create(ctx, 1, &CreateAPIKeyRequest{Name: "ci-2", Scopes: []string{"read"}, TTL: time.Hour})
+	assert.ErrorIs(t, err, pkgErrors.ErrOperationNotAllowed, "超过每个用户的密钥数")
+
+	// 只能撤销自己的密钥
+	_, err = service.Revoke(ctx, 2, created.APIKey.ID)
+	assert.ErrorIs(t, err, pkgErrors.ErrResourceNotFound)
+	revoked, err := service.Revoke(ctx, 1, created.APIKey.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "ci", revoked.Name)
+	_, _, err = service.Authenticate(ctx, created.Key, "10.0.0.1")
+	assert.ErrorIs(t, err, pkgErrors.ErrPermissionDenied, "撤销后不能使用")
+
+	// 账户停用后密钥不能使用
+	created, err = service.Create(ctx, 2, &CreateAPIKeyRequest{Name: "ci", Scopes: []string{"read"}, TTL: time.Hour})
+	require.NoError(t, err)
+	_, _, err = service.Authenticate(ctx, created.Key, "10.0.0.1")
+	assert.ErrorIs(t, err, pkgErrors.ErrPermissionDenied)
+}
@@ -0,0 +1,597 @@
+package user
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/cache"
+	"cloudpan/internal/pkg/config"
+	"cloudpan/internal/pkg/email"
+	"cloudpan/internal/repository/models"
+	userrepo "cloudpan/internal/repository/user"
+	"cloudpan/internal/service/audit"
+)
+
+// 长期未登录的处置方式
+const (
+	InactivityActionNone      = "none"      // 不处置，只在配置了删除时计划删除
+	InactivityActionDowngrade = "downgrade" // 降低存储配额，超出的文件可下载和删除但不能上传
+	InactivityActionFreeze    = "freeze"    // 冻结账户(禁用状态)并吊销已签发的令牌
+)
+
+// 提醒邮件中的处置步骤
+const (
+	inactivityEmailDowngrade = "downgrade"
+	inactivityEmailFreeze    = "freeze"
+	inactivityEmailDelete    = "delete"
+)
+
+// 长期未登录策略默认值
+const (
+	DefaultInactiveMonths        = 12
+	DefaultInactivityDeleteGrace = 30 * 24 * time.Hour
+	DefaultInactivityBatchSize   = 100
+)
+
+// DefaultInactivityWarningDays 默认在每一步执行前30天、7天和1天发送提醒
+var DefaultInactivityWarningDays = []int{30, 7, 1}
+
+// InactivityService 长期未登录账户处置服务接口
+//
+// 按策略处理长期未登录的账户，每一步执行前发送逐步升级的提醒邮件：
+// 1. 未登录超过 InactiveMonths 个月时执行配置的处置：降低存储配额或冻结账户
+// 2. 未登录超过 DeleteAfterMonths 个月时计划删除账户，恢复期限内可通过重新激活接口恢复
+//
+// 每一步的执行时间不早于第一次提醒后 max(WarningDays) 天，启用策略时已长期未登录的用户同样有完整的提醒期。
+// 用户重新登录(完成两步验证，刷新令牌不算)时恢复降低的配额和冻结的账户；发送提醒、执行和恢复处置都写入安全审计日志
+//
+// 使用示例：
+//
+//	service := NewInactivityService(userrepo.NewInactivityRepository(db), email.GlobalQueue{}, tokenStore, audit.DefaultSecurity(), options, logger)
+//	processed, err := service.Enforce(ctx, 100)
+//	frozen, err := service.IsFrozen(ctx, user)
+//	restored, err := service.RecordLogin(ctx, user, clientIP)
+type InactivityService interface {
+	Enforce(ctx context.Context, batchSize int) (int64, error)
+	RecordLogin(ctx context.Context, user *models.User, ip string) (bool, error)
+	IsFrozen(ctx context.Context, user *models.User) (bool, error)
+	ListStates(ctx context.Context, page, pageSize int) ([]*models.UserInactivity, int64, error)
+}
+
+// InactivityStore 长期未登录处置需要的数据访问，由 userrepo.InactivityRepository 实现
+type InactivityStore interface {
+	ListCandidates(ctx context.Context, filter userrepo.InactivityCandidateFilter, afterID uint, limit int) ([]*models.User, error)
+	GetStates(ctx context.Context, userIDs []uint) (map[uint]*models.UserInactivity, error)
+	GetState(ctx context.Context, userID uint) (*models.UserInactivity, error)
+	SaveState(ctx context.Context, state *models.UserInactivity) error
+	ApplyStep(ctx context.Context, state *models.UserInactivity, updates map[string]interface{}) (bool, error)
+	Release(ctx context.Context, userID uint, updates map[string]interface{}) error
+	ListStates(ctx context.Context, offset, limit int) ([]*models.UserInactivity, int64, error)
+}
+
+// InactivityOptions 长期未登录策略选项
+type InactivityOptions struct {
+	AppName           string        // 邮件中显示的应用名称
+	LoginURL          string        // 邮件中的登录页面地址
+	InactiveMonths    int           // 未登录多少个月后执行处置
+	Action            string        // 处置方式：none/downgrade/freeze
+	DowngradedQuota   int64         // downgrade时降低到的存储配额(字节)
+	DeleteAfterMonths int           // 未登录多少个月后计划删除账户，0表示不删除
+	DeletionGrace     time.Duration // 计划删除后可重新激活的期限
+	WarningDays       []int         // 每一步执行前多少天发送提醒
+	ExemptRoles       []string      // 不受策略影响的角色
+}
+
+// InactivityOptionsFromConfig 从配置生成长期未登录策略选项
+func InactivityOptionsFromConfig(appName string, cfg config.InactivityConfig) InactivityOptions {
+	return InactivityOptions{
+		AppName:           appName,
+		LoginURL:          cfg.LoginURL,
+		InactiveMonths:    cfg.InactiveMonths,
+		Action:            cfg.Action,
+		DowngradedQuota:   cfg.DowngradedQuota,
+		DeleteAfterMonths: cfg.DeleteAfterMonths,
+		DeletionGrace:     cfg.DeletionGrace,
+		WarningDays:       cfg.WarningDays,
+		ExemptRoles:       cfg.ExemptRoles,
+	}
+}
+
+// inactivityService 长期未登录账户处置服务实现
+type inactivityService struct {
+	store      InactivityStore
+	queue      EmailQueuer
+	tokenStore cache.TokenStore
+	security   audit.SecurityAuditService
+	options    InactivityOptions
+	warnings   []int         // 提醒天数，从大到小
+	warnPeriod time.Duration // 第一次提醒到执行的最短间隔
+	logger     *zap.Logger
+	now        func() time.Time
+}
+
+// NewInactivityService 创建长期未登录账户处置服务，未配置的选项使用默认值
+//
+// tokenStore 为nil时冻结账户不吊销已签发的令牌；security 为nil时不写安全审计日志
+func NewInactivityService(store InactivityStore, queue EmailQueuer, tokenStore cache.TokenStore, security audit.SecurityAuditService, options InactivityOptions, logger *zap.Logger) InactivityService {
+	if options.InactiveMonths <= 0 {
+		options.InactiveMonths = DefaultInactiveMonths
+	}
+	if options.Action == "" {
+		options.Action = InactivityActionNone
+	}
+	// 配额为0表示不限制，不能作为降低后的配额
+	if options.Action == InactivityActionDowngrade && options.DowngradedQuota <= 0 {
+		options.Action = InactivityActionNone
+	}
+	if options.DeleteAfterMonths != 0 && options.DeleteAfterMonths <= options.InactiveMonths {
+		options.DeleteAfterMonths = 0
+	}
+	if options.DeletionGrace <= 0 {
+		options.DeletionGrace = DefaultInactivityDeleteGrace
+	}
+	if options.WarningDays == nil {
+		options.WarningDays = DefaultInactivityWarningDays
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	warnings := uniqueWarningDays(options.WarningDays)
+	var warnPeriod time.Duration
+	if len(warnings) > 0 {
+		warnPeriod = time.Duration(warnings[0]) * 24 * time.Hour
+	}
+	return &inactivityService{
+		store:      store,
+		queue:      queue,
+		tokenStore: tokenStore,
+		security:   security,
+		options:    options,
+		warnings:   warnings,
+		warnPeriod: warnPeriod,
+		logger:     logger,
+		now:        time.Now,
+	}
+}
+
+// Enforce 处理全部候选用户：发送到期的提醒，执行到期的处置，返回发送的提醒和执行的处置数
+//
+// batchSize 为每次查询的用户数；单个用户处理失败时记录日志后继续
+func (s *inactivityService) Enforce(ctx context.Context, batchSize int) (int64, error) {
+	firstStep := s.firstStep()
+	if firstStep == "" {
+		return 0, nil
+	}
+	if batchSize <= 0 {
+		batchSize = DefaultInactivityBatchSize
+	}
+
+	now := s.now()
+	filter := userrepo.InactivityCandidateFilter{
+		ActiveBefore: now.Add(s.warnPeriod).AddDate(0, -s.stepMonths(firstStep), 0),
+		ExemptRoles:  s.options.ExemptRoles,
+		Now:          now,
+	}
+
+	var processed int64
+	var afterID uint
+	for {
+		users, err := s.store.ListCandidates(ctx, filter, afterID, batchSize)
+		if err != nil {
+			return processed, err
+		}
+		if len(users) == 0 {
+			break
+		}
+
+		ids := make([]uint, 0, len(users))
+		for _, user := range users {
+			ids = append(ids, user.ID)
+		}
+		states, err := s.store.GetStates(ctx, ids)
+		if err != nil {
+			return processed, err
+		}
+		for _, user := range users {
+			done, err := s.process(ctx, user, states[user.ID], now)
+			if err != nil {
+				s.logger.Error("Failed to enforce inactivity policy",
+					zap.Uint("user_id", user.ID),
+					zap.Error(err))
+				continue
+			}
+			if done {
+				processed++
+			}
+		}
+
+		if len(users) < batchSize {
+			break
+		}
+		afterID = users[len(users)-1].ID
+	}
+
+	if processed > 0 {
+		s.logger.Info("Inactivity policy enforced", zap.Int64("processed", processed))
+	}
+	return processed, nil
+}
+
+// RecordLogin 记录用户登录时间和IP，恢复长期未登录策略执行的处置并更新 user，有处置被恢复时返回true
+//
+// 降低的配额恢复为原配额(管理员期间调高的保持不变)，被策略冻结的账户恢复正常状态；
+// 计划删除需通过重新激活接口取消，已删除的账户不处理
+func (s *inactivityService) RecordLogin(ctx context.Context, user *models.User, ip string) (bool, error) {
+	if user.Status == "deleted" {
+		return false, nil
+	}
+	state, err := s.store.GetState(ctx, user.ID)
+	if err != nil {
+		return false, err
+	}
+
+	now := s.now()
+	updates := map[string]interface{}{"last_login_at": now}
+	if ip != "" {
+		updates["last_login_ip"] = ip
+	}
+	restored := map[string]interface{}{}
+	if state != nil {
+		if state.OriginalQuota != nil && quotaBelow(user.StorageQuota, *state.OriginalQuota) {
+			updates["storage_quota"] = *state.OriginalQuota
+			restored["storage_quota"] = *state.OriginalQuota
+		}
+		if state.FrozenAt != nil && user.Status == "inactive" {
+			updates["status"] = "active"
+			restored["status"] = "active"
+		}
+	}
+
+	if err := s.store.Release(ctx, user.ID, updates); err != nil {
+		return false, err
+	}
+
+	user.LastLoginAt = &now
+	if ip != "" {
+		user.LastLoginIP = &ip
+	}
+	if state == nil {
+		return false, nil
+	}
+	if quota, ok := restored["storage_quota"].(int64); ok {
+		user.StorageQuota = quota
+	}
+	if _, ok := restored["status"]; ok {
+		user.Status = "active"
+	}
+
+	s.logger.Info("Inactivity state cleared on login",
+		zap.Uint("user_id", user.ID),
+		zap.String("step", state.Step),
+		zap.Int("restored", len(restored)))
+	s.record(ctx, &audit.Event{
+		UserID:       user.ID,
+		Action:       audit.SecurityActionInactivityRestore,
+		ResourceType: audit.ResourceUser,
+		ResourceID:   strconv.FormatUint(uint64(user.ID), 10),
+		ResourceName: user.Username,
+		Before:       inactivitySnapshot(state),
+		After:        restored,
+		IPAddress:    ip,
+	})
+	return len(restored) > 0, nil
+}
+
+// IsFrozen 账户是否处于被策略冻结的禁用状态，不修改任何数据
+//
+// 登录时据此区分策略冻结和管理员禁用：策略冻结的账户通过完整的登录验证后由 RecordLogin 恢复
+func (s *inactivityService) IsFrozen(ctx context.Context, user *models.User) (bool, error) {
+	if user.Status != "inactive" {
+		return false, nil
+	}
+	state, err := s.store.GetState(ctx, user.ID)
+	if err != nil {
+		return false, err
+	}
+	return state != nil && state.FrozenAt != nil, nil
+}
+
+// ListStates 分页获取处于提醒期或已被处置的用户
+func (s *inactivityService) ListStates(ctx context.Context, page, pageSize int) ([]*models.UserInactivity, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	return s.store.ListStates(ctx, (page-1)*pageSize, pageSize)
+}
+
+// process 处理一个候选用户，发送了提醒或执行了处置时返回true
+func (s *inactivityService) process(ctx context.Context, user *models.User, state *models.UserInactivity, now time.Time) (bool, error) {
+	activity := lastActivity(user)
+	// 最后活动时间变化说明用户已重新登录，之前的状态作废
+	if state != nil && activity.After(state.LastActiveAt) {
+		if err := s.store.Release(ctx, user.ID, nil); err != nil {
+			return false, err
+		}
+		state = nil
+	}
+	if state == nil {
+		// 管理员禁用的账户不由策略处理
+		if user.Status != "active" {
+			return false, nil
+		}
+		state = &models.UserInactivity{UserID: user.ID, LastActiveAt: activity, Step: s.firstStep()}
+	}
+	if state.Step == models.InactivityStepDone {
+		return false, nil
+	}
+
+	if state.StepDueAt == nil {
+		stepAt := activity.AddDate(0, s.stepMonths(state.Step), 0)
+		if now.Before(stepAt.Add(-s.warnPeriod)) {
+			return false, nil
+		}
+		due := stepAt
+		if earliest := now.Add(s.warnPeriod); due.Before(earliest) {
+			due = earliest
+		}
+		state.StepDueAt = &due
+		state.WarningsSent = 0
+	}
+
+	due := *state.StepDueAt
+	if level := s.warningLevel(due, now); level > state.WarningsSent {
+		return true, s.warn(ctx, user, state, level, now)
+	}
+	if now.Before(due) {
+		return false, nil
+	}
+	return s.applyStep(ctx, user, state, now)
+}
+
+// warn 发送第level次提醒并保存状态，错过的较早提醒不再补发
+func (s *inactivityService) warn(ctx context.Context, user *models.User, state *models.UserInactivity, level int, now time.Time) error {
+	due := *state.StepDueAt
+	daysLeft := int(math.Ceil(due.Sub(now).Hours() / 24))
+	final := level == len(s.warnings)
+	step := s.emailStep(state.Step)
+
+	state.WarningsSent = level
+	state.LastWarnedAt = &now
+	if err := s.store.SaveState(ctx, state); err != nil {
+		return err
+	}
+
+	// 状态已保存，邮件入队失败时不重发，处置仍按计划执行
+	err := s.queue.QueueEmail(&email.EmailQueue{
+		To:       []string{user.Email},
+		Template: email.TemplateInactivityWarning,
+		Variables: map[string]interface{}{
+			"app_name":    s.options.AppName,
+			"username":    user.Username,
+			"step":        step,
+			"action_date": due.Format("2006-01-02"),
+			"days_left":   daysLeft,
+			"final":       final,
+			"login_url":   s.options.LoginURL,
+		},
+		Language: user.PreferredLanguage(),
+		Priority: email.PriorityNormal,
+	})
+	if err != nil {
+		s.logger.Warn("Failed to queue inactivity warning email",
+			zap.Uint("user_id", user.ID),
+			zap.Error(err))
+	}
+
+	s.record(ctx, &audit.Event{
+		UserID:       user.ID,
+		Action:       audit.SecurityActionInactivityWarning,
+		ResourceType: audit.ResourceUser,
+		ResourceID:   strconv.FormatUint(uint64(user.ID), 10),
+		ResourceName: user.Username,
+		Details: map[string]interface{}{
+			"step":      step,
+			"due_at":    due,
+			"warning":   level,
+			"final":     final,
+			"queued":    err == nil,
+			"last_seen": state.LastActiveAt,
+		},
+	})
+	return nil
+}
+
+// applyStep 执行当前步骤并进入下一步，用户期间重新登录时不执行
+func (s *inactivityService) applyStep(ctx context.Context, user *models.User, state *models.UserInactivity, now time.Time) (bool, error) {
+	before := map[string]interface{}{"status": user.Status, "storage_quota": user.StorageQuota}
+	updates := map[string]interface{}{"updated_at": now}
+	action := ""
+	freeze := false
+
+	switch {
+	case state.Step == models.InactivityStepDeletion:
+		scheduledAt := now.Add(s.options.DeletionGrace)
+		updates["status"] = "deleted"
+		updates["deletion_scheduled_at"] = scheduledAt
+		state.DeletionScheduledAt = &scheduledAt
+		action = audit.SecurityActionInactivityDeletion
+	case s.options.Action == InactivityActionDowngrade:
+		if quotaBelow(s.options.DowngradedQuota, user.StorageQuota) {
+			updates["storage_quota"] = s.options.DowngradedQuota
+			original := user.StorageQuota
+			state.OriginalQuota = &original
+		}
+		state.DowngradedAt = &now
+		action = audit.SecurityActionInactivityDowngrade
+	case s.options.Action == InactivityActionFreeze:
+		updates["status"] = "inactive"
+		state.FrozenAt = &now
+		action = audit.SecurityActionInactivityFreeze
+		freeze = true
+	}
+
+	step := state.Step
+	state.Step = s.nextStep(state.Step)
+	state.StepDueAt = nil
+	state.WarningsSent = 0
+	applied, err := s.store.ApplyStep(ctx, state, updates)
+	if err != nil || !applied {
+		return false, err
+	}
+
+	if freeze && s.tokenStore != nil {
+		if err := s.tokenStore.RevokeUserTokens(ctx, uint64(user.ID), now); err != nil {
+			s.logger.Warn("Failed to revoke tokens of frozen account",
+				zap.Uint("user_id", user.ID),
+				zap.Error(err))
+		}
+	}
+
+	after := map[string]interface{}{}
+	for _, key := range []string{"status", "storage_quota", "deletion_scheduled_at"} {
+		if value, ok := updates[key]; ok {
+			after[key] = value
+		}
+	}
+	s.logger.Info("Inactivity policy step applied",
+		zap.Uint("user_id", user.ID),
+		zap.String("step", step),
+		zap.String("action", action),
+		zap.Time("last_active_at", state.LastActiveAt))
+	s.record(ctx, &audit.Event{
+		UserID:       user.ID,
+		Action:       action,
+		ResourceType: audit.ResourceUser,
+		ResourceID:   strconv.FormatUint(uint64(user.ID), 10),
+		ResourceName: user.Username,
+		Before:       before,
+		After:        after,
+		Details:      map[string]interface{}{"last_seen": state.LastActiveAt},
+	})
+	return true, nil
+}
+
+// firstStep 第一步，策略不处置也不删除时返回空
+func (s *inactivityService) firstStep() string {
+	if s.options.Action == InactivityActionDowngrade || s.options.Action == InactivityActionFreeze {
+		return models.InactivityStepAction
+	}
+	if s.options.DeleteAfterMonths > 0 {
+		return models.InactivityStepDeletion
+	}
+	return ""
+}
+
+// nextStep 执行 step 后的下一步
+func (s *inactivityService) nextStep(step string) string {
+	if step == models.InactivityStepAction && s.options.DeleteAfterMonths > 0 {
+		return models.InactivityStepDeletion
+	}
+	return models.InactivityStepDone
+}
+
+// stepMonths 最后活动后多少个月执行 step
+func (s *inactivityService) stepMonths(step string) int {
+	if step == models.InactivityStepDeletion {
+		return s.options.DeleteAfterMonths
+	}
+	return s.options.InactiveMonths
+}
+
+// emailStep 提醒邮件中的处置步骤
+func (s *inactivityService) emailStep(step string) string {
+	switch {
+	case step == models.InactivityStepDeletion:
+		return inactivityEmailDelete
+	case s.options.Action == InactivityActionFreeze:
+		return inactivityEmailFreeze
+	default:
+		return inactivityEmailDowngrade
+	}
+}
+
+// warningLevel 到 now 为止应发送的提醒次数
+func (s *inactivityService) warningLevel(due, now time.Time) int {
+	level := 0
+	for _, days := range s.warnings {
+		if !now.Before(due.Add(-time.Duration(days) * 24 * time.Hour)) {
+			level++
+		}
+	}
+	return level
+}
+
+// record 写入安全审计日志，失败时只记录日志
+func (s *inactivityService) record(ctx context.Context, event *audit.Event) {
+	if s.security == nil {
+		return
+	}
+	event.Details = withPolicyDetail(event.Details)
+	if err := s.security.Record(ctx, event); err != nil {
+		s.logger.Error("Failed to record inactivity audit event",
+			zap.Uint("user_id", event.UserID),
+			zap.String("action", event.Action),
+			zap.Error(err))
+	}
+}
+
+// withPolicyDetail 标记审计记录由长期未登录策略产生
+func withPolicyDetail(details map[string]interface{}) map[string]interface{} {
+	if details == nil {
+		details = make(map[string]interface{}, 1)
+	}
+	details["policy"] = "inactivity"
+	return details
+}
+
+// inactivitySnapshot 审计记录中的处置状态
+func inactivitySnapshot(state *models.UserInactivity) map[string]interface{} {
+	snapshot := map[string]interface{}{"step": state.Step, "warnings_sent": state.WarningsSent}
+	if state.OriginalQuota != nil {
+		snapshot["original_quota"] = *state.OriginalQuota
+	}
+	if state.FrozenAt != nil {
+		snapshot["frozen_at"] = *state.FrozenAt
+	}
+	return snapshot
+}
+
+// quotaBelow 存储配额a是否小于b，0表示不限制
+func quotaBelow(a, b int64) bool {
+	if b == 0 {
+		return a != 0
+	}
+	return a != 0 && a < b
+}
+
+// lastActivity 用户最后活动时间，从未登录时为注册时间
+func lastActivity(user *models.User) time.Time {
+	if user.LastLoginAt != nil {
+		return *user.LastLoginAt
+	}
+	return user.CreatedAt
+}
+
+// uniqueWarningDays 去除重复和非正数的提醒天数，从大到小排列
+func uniqueWarningDays(days []int) []int {
+	seen := make(map[int]bool, len(days))
+	result := make([]int, 0, len(days))
+	for _, day := range days {
+		if day <= 0 || seen[day] {
+			continue
+		}
+		seen[day] = true
+		result = append(result, day)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(result)))
+	return result
+}
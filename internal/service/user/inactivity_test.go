@@ -0,0 +1,414 @@
+package user
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/cache"
+	"cloudpan/internal/pkg/email"
+	"cloudpan/internal/repository/models"
+	userrepo "cloudpan/internal/repository/user"
+	"cloudpan/internal/service/audit"
+)
+
+// memoryInactivityStore 进程内长期未登录数据，按仓储的查询条件筛选候选用户
+type memoryInactivityStore struct {
+	mu     sync.Mutex
+	users  map[uint]*models.User
+	roles  map[uint][]string
+	states map[uint]*models.UserInactivity
+}
+
+func newMemoryInactivityStore(users ...*models.User) *memoryInactivityStore {
+	store := &memoryInactivityStore{
+		users:  make(map[uint]*models.User),
+		roles:  make(map[uint][]string),
+		states: make(map[uint]*models.UserInactivity),
+	}
+	for _, user := range users {
+		store.users[user.ID] = user
+	}
+	return store
+}
+
+func (m *memoryInactivityStore) ListCandidates(_ context.Context, filter userrepo.InactivityCandidateFilter, afterID uint, limit int) ([]*models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var users []*models.User
+	for id, user := range m.users {
+		state := m.states[id]
+		if id <= afterID || lastActivity(user).After(filter.ActiveBefore) || m.exempt(id, filter.ExemptRoles) {
+			continue
+		}
+		if state != nil && state.Step == models.InactivityStepDone {
+			continue
+		}
+		if user.Status == "active" || user.Status == "inactive" && state != nil && state.FrozenAt != nil {
+			copied := *user
+			users = append(users, &copied)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	if len(users) > limit {
+		users = users[:limit]
+	}
+	return users, nil
+}
+
+func (m *memoryInactivityStore) exempt(id uint, roles []string) bool {
+	for _, role := range m.roles[id] {
+		for _, exempt := range roles {
+			if role == exempt {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (m *memoryInactivityStore) GetStates(_ context.Context, userIDs []uint) (map[uint]*models.UserInactivity, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	states := make(map[uint]*models.UserInactivity)
+	for _, id := range userIDs {
+		if state, ok := m.states[id]; ok {
+			copied := *state
+			states[id] = &copied
+		}
+	}
+	return states, nil
+}
+
+func (m *memoryInactivityStore) GetState(_ context.Context, userID uint) (*models.UserInactivity, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	state, ok := m.states[userID]
+	if !ok {
+		return nil, nil
+	}
+	copied := *state
+	return &copied, nil
+}
+
+func (m *memoryInactivityStore) SaveState(_ context.Context, state *models.UserInactivity) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	copied := *state
+	m.states[state.UserID] = &copied
+	return nil
+}
+
+func (m *memoryInactivityStore) ApplyStep(_ context.Context, state *models.UserInactivity, updates map[string]interface{}) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	user := m.users[state.UserID]
+	if user.Status != "active" && user.Status != "inactive" || lastActivity(user).After(state.LastActiveAt) {
+		return false, nil
+	}
+	applyUserUpdates(user, updates)
+	copied := *state
+	m.states[state.UserID] = &copied
+	return true, nil
+}
+
+func (m *memoryInactivityStore) Release(_ context.Context, userID uint, updates map[string]interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	applyUserUpdates(m.users[userID], updates)
+	delete(m.states, userID)
+	return nil
+}
+
+func (m *memoryInactivityStore) ListStates(_ context.Context, offset, limit int) ([]*models.UserInactivity, int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var states []*models.UserInactivity
+	for _, state := range m.states {
+		states = append(states, state)
+	}
+	return states, int64(len(states)), nil
+}
+
+func (m *memoryInactivityStore) user(id uint) models.User {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return *m.users[id]
+}
+
+func (m *memoryInactivityStore) state(id uint) *models.UserInactivity {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.states[id]
+}
+
+func applyUserUpdates(user *models.User, updates map[string]interface{}) {
+	for key, value := range updates {
+		switch key {
+		case "status":
+			user.Status = value.(string)
+		case "storage_quota":
+			user.StorageQuota = value.(int64)
+		case "deletion_scheduled_at":
+			at := value.(time.Time)
+			user.DeletionScheduledAt = &at
+		case "last_login_at":
+			at := value.(time.Time)
+			user.LastLoginAt = &at
+		}
+	}
+}
+
+// recordingSecurityAudit 记录写入的安全审计事件
+type recordingSecurityAudit struct {
+	audit.SecurityAuditService
+	events []*audit.Event
+}
+
+func (r *recordingSecurityAudit) Record(_ context.Context, events ...*audit.Event) error {
+	r.events = append(r.events, events...)
+	return nil
+}
+
+func (r *recordingSecurityAudit) actions() []string {
+	actions := make([]string, 0, len(r.events))
+	for _, event := range r.events {
+		actions = append(actions, event.Action)
+	}
+	return actions
+}
+
+var inactivityTestNow = time.Date(2024, 7, 1, 3, 0, 0, 0, time.UTC)
+
+func inactiveTestUser(id uint, lastLogin time.Time) *models.User {
+	user := resetTestUser(id, "inactive@example.com")
+	user.Status = "active"
+	user.StorageQuota = 10 << 30
+	user.LastLoginAt = &lastLogin
+	return user
+}
+
+func newTestInactivityService(store InactivityStore, options InactivityOptions) (*inactivityService, *recordingEmailQueue, *cache.MemoryTokenStore, *recordingSecurityAudit) {
+	queue := &recordingEmailQueue{}
+	tokens := cache.NewMemoryTokenStore(0)
+	security := &recordingSecurityAudit{}
+	options.AppName = "CloudPan"
+	svc := NewInactivityService(store, queue, tokens, security, options, zap.NewNop()).(*inactivityService)
+	svc.now = func() time.Time { return inactivityTestNow }
+	return svc, queue, tokens, security
+}
+
+func TestInactivityService_DowngradeWithEscalatingWarnings(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryInactivityStore(
+		inactiveTestUser(1, inactivityTestNow.AddDate(-1, -1, 0)),
+		inactiveTestUser(2, inactivityTestNow.AddDate(0, -3, 0)),
+	)
+	svc, queue, _, security := newTestInactivityService(store, InactivityOptions{
+		InactiveMonths:    12,
+		Action:            InactivityActionDowngrade,
+		DowngradedQuota:   1 << 30,
+		DeleteAfterMonths: 24,
+	})
+
+	// 超过期限才启用策略时仍有完整的提醒期
+	processed, err := svc.Enforce(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), processed)
+	require.Len(t, queue.queued, 1)
+	assert.Equal(t, email.TemplateInactivityWarning, queue.queued[0].Template)
+	assert.Equal(t, "downgrade", queue.queued[0].Variables["step"])
+	assert.Equal(t, 30, queue.queued[0].Variables["days_left"])
+	assert.Equal(t, false, queue.queued[0].Variables["final"])
+	assert.Equal(t, "2024-07-31", queue.queued[0].Variables["action_date"])
+	assert.Nil(t, store.state(2))
+
+	processed, err = svc.Enforce(ctx, 1)
+	require.NoError(t, err)
+	assert.Zero(t, processed)
+
+	svc.now = func() time.Time { return inactivityTestNow.AddDate(0, 0, 23) }
+	_, err = svc.Enforce(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, queue.queued, 2)
+	assert.Equal(t, 7, queue.queued[1].Variables["days_left"])
+
+	// 最后一次提醒
+	svc.now = func() time.Time { return inactivityTestNow.AddDate(0, 0, 29).Add(time.Hour) }
+	_, err = svc.Enforce(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, queue.queued, 3)
+	assert.Equal(t, true, queue.queued[2].Variables["final"])
+	assert.Equal(t, int64(10<<30), store.user(1).StorageQuota)
+
+	svc.now = func() time.Time { return inactivityTestNow.AddDate(0, 0, 30) }
+	processed, err = svc.Enforce(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), processed)
+	assert.Equal(t, int64(1<<30), store.user(1).StorageQuota)
+	state := store.state(1)
+	require.NotNil(t, state)
+	assert.Equal(t, models.InactivityStepDeletion, state.Step)
+	require.NotNil(t, state.OriginalQuota)
+	assert.Equal(t, int64(10<<30), *state.OriginalQuota)
+
+	// 重新登录恢复配额并清除状态
+	user := store.user(1)
+	restored, err := svc.RecordLogin(ctx, &user, "203.0.113.7")
+	require.NoError(t, err)
+	assert.True(t, restored)
+	assert.Equal(t, int64(10<<30), user.StorageQuota)
+	assert.Equal(t, int64(10<<30), store.user(1).StorageQuota)
+	assert.Nil(t, store.state(1))
+	require.NotNil(t, store.user(1).LastLoginAt)
+
+	assert.Equal(t, []string{
+		audit.SecurityActionInactivityWarning,
+		audit.SecurityActionInactivityWarning,
+		audit.SecurityActionInactivityWarning,
+		audit.SecurityActionInactivityDowngrade,
+		audit.SecurityActionInactivityRestore,
+	}, security.actions())
+	assert.Equal(t, "inactivity", security.events[3].Details["policy"])
+}
+
+func TestInactivityService_FreezeWithoutWarnings(t *testing.T) {
+	ctx := context.Background()
+	disabled := inactiveTestUser(3, inactivityTestNow.AddDate(-2, 0, 0))
+	disabled.Status = "inactive"
+	store := newMemoryInactivityStore(
+		inactiveTestUser(1, inactivityTestNow.AddDate(-2, 0, 0)),
+		inactiveTestUser(2, inactivityTestNow.AddDate(-2, 0, 0)),
+		disabled,
+	)
+	store.roles[2] = []string{"admin"}
+	svc, queue, tokens, security := newTestInactivityService(store, InactivityOptions{
+		InactiveMonths: 6,
+		Action:         InactivityActionFreeze,
+		WarningDays:    []int{},
+		ExemptRoles:    []string{"admin"},
+	})
+
+	processed, err := svc.Enforce(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), processed)
+	assert.Empty(t, queue.queued)
+	assert.Equal(t, "inactive", store.user(1).Status)
+	assert.Equal(t, "active", store.user(2).Status)
+	assert.Nil(t, store.state(3))
+	revokedAt, err := tokens.RevokedBefore(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, inactivityTestNow, revokedAt)
+	assert.Equal(t, models.InactivityStepDone, store.state(1).Step)
+
+	// 全部步骤已执行的用户不再处理
+	processed, err = svc.Enforce(ctx, 10)
+	require.NoError(t, err)
+	assert.Zero(t, processed)
+
+	user := store.user(1)
+	frozen, err := svc.IsFrozen(ctx, &user)
+	require.NoError(t, err)
+	assert.True(t, frozen)
+	disabledUser := store.user(3)
+	frozen, err = svc.IsFrozen(ctx, &disabledUser)
+	require.NoError(t, err)
+	assert.False(t, frozen, "管理员禁用的账户不是策略冻结")
+
+	restored, err := svc.RecordLogin(ctx, &user, "")
+	require.NoError(t, err)
+	assert.True(t, restored)
+	assert.Equal(t, "active", user.Status)
+	assert.Equal(t, "active", store.user(1).Status)
+	assert.Equal(t, []string{audit.SecurityActionInactivityFreeze, audit.SecurityActionInactivityRestore}, security.actions())
+
+	// 没有处置状态时只记录登录时间
+	user = store.user(2)
+	restored, err = svc.RecordLogin(ctx, &user, "")
+	require.NoError(t, err)
+	assert.False(t, restored)
+	assert.Equal(t, inactivityTestNow, *store.user(2).LastLoginAt)
+	assert.Len(t, security.events, 2)
+}
+
+func TestInactivityService_ScheduleDeletion(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryInactivityStore(inactiveTestUser(1, inactivityTestNow.AddDate(-3, 0, 0)))
+	svc, queue, _, security := newTestInactivityService(store, InactivityOptions{
+		InactiveMonths:    12,
+		DeleteAfterMonths: 24,
+		DeletionGrace:     48 * time.Hour,
+		WarningDays:       []int{7},
+	})
+
+	_, err := svc.Enforce(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, queue.queued, 1)
+	assert.Equal(t, "delete", queue.queued[0].Variables["step"])
+	assert.Equal(t, true, queue.queued[0].Variables["final"])
+
+	svc.now = func() time.Time { return inactivityTestNow.AddDate(0, 0, 7) }
+	processed, err := svc.Enforce(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), processed)
+	user := store.user(1)
+	assert.Equal(t, "deleted", user.Status)
+	require.NotNil(t, user.DeletionScheduledAt)
+	assert.Equal(t, inactivityTestNow.AddDate(0, 0, 7).Add(48*time.Hour), *user.DeletionScheduledAt)
+	assert.Equal(t, models.InactivityStepDone, store.state(1).Step)
+	assert.Equal(t, audit.SecurityActionInactivityDeletion, security.events[1].Action)
+
+	// 计划删除需通过重新激活取消
+	restored, err := svc.RecordLogin(ctx, &user, "")
+	require.NoError(t, err)
+	assert.False(t, restored)
+	assert.NotNil(t, store.state(1))
+}
+
+func TestInactivityService_ActivityDuringWarning(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryInactivityStore(inactiveTestUser(1, inactivityTestNow.AddDate(-1, 0, -10)))
+	svc, queue, _, _ := newTestInactivityService(store, InactivityOptions{
+		InactiveMonths: 12,
+		Action:         InactivityActionFreeze,
+	})
+
+	_, err := svc.Enforce(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, queue.queued, 1)
+	require.NotNil(t, store.state(1))
+
+	// 用户期间活动过(如经其他入口登录)，之前的状态作废
+	store.mu.Lock()
+	loginAt := inactivityTestNow.AddDate(-1, 0, 20)
+	store.users[1].LastLoginAt = &loginAt
+	store.mu.Unlock()
+
+	svc.now = func() time.Time { return inactivityTestNow.AddDate(0, 0, 30) }
+	_, err = svc.Enforce(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, "active", store.user(1).Status)
+	state := store.state(1)
+	require.NotNil(t, state)
+	assert.Equal(t, loginAt, state.LastActiveAt)
+	assert.Equal(t, 1, state.WarningsSent)
+}
+
+func TestQuotaBelow(t *testing.T) {
+	assert.True(t, quotaBelow(1, 2))
+	assert.False(t, quotaBelow(2, 2))
+	assert.True(t, quotaBelow(1<<30, 0)) // 0表示不限制
+	assert.False(t, quotaBelow(0, 1<<30))
+	assert.False(t, quotaBelow(0, 0))
+}
+
+func TestUniqueWarningDays(t *testing.T) {
+	assert.Equal(t, []int{30, 7, 1}, uniqueWarningDays([]int{1, 7, 30, 7, 0, -3}))
+	assert.Empty(t, uniqueWarningDays(nil))
+}
@@ -0,0 +1,388 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/cache"
+	"cloudpan/internal/pkg/config"
+	"cloudpan/internal/pkg/email"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/repository/models"
+)
+
+// 批量强制重置密码默认值
+const (
+	DefaultPasswordResetBatchSize     = 50
+	DefaultPasswordResetBatchInterval = 5 * time.Second
+	DefaultPasswordResetMaxUsers      = 10000
+
+	// passwordResetJobRetention 已结束的任务在内存中保留的时间
+	passwordResetJobRetention = 24 * time.Hour
+)
+
+// 批量重置任务状态
+const (
+	PasswordResetJobRunning   = "running"   // 执行中
+	PasswordResetJobCompleted = "completed" // 已完成(个别用户失败见failed_user_ids)
+)
+
+// PasswordResetService 批量强制重置密码服务接口
+//
+// 安全事件后管理员对一批用户强制重置密码：
+// 1. 标记用户必须重置密码，标记期间禁止登录和刷新令牌
+// 2. 通过TokenStore吊销用户已签发的全部令牌并清除刷新令牌登记，已登录的设备立即失效
+// 3. 分批将重置邮件加入邮件队列，避免一次性占满队列
+//
+// 标记和吊销在发送邮件前全部完成，邮件发送慢或失败不影响账户保护；
+// 任务进度保存在当前实例内存中，查询需要路由到发起任务的实例
+//
+// 使用示例：
+//
+//	service := NewPasswordResetService(userRepo, tokenStore, refreshTokens, email.GlobalQueue{}, options, logger)
+//	job, err := service.StartBulkReset(ctx, adminID, BulkPasswordResetRequest{UserIDs: ids, Reason: "凭据泄露"})
+//	job, err = service.GetJob(ctx, job.ID)
+type PasswordResetService interface {
+	StartBulkReset(ctx context.Context, adminID uint, req BulkPasswordResetRequest) (*PasswordResetJob, error)
+	GetJob(ctx context.Context, jobID string) (*PasswordResetJob, error)
+}
+
+// PasswordResetUserStore 批量重置需要的用户数据访问，由 userrepo.UserRepository 实现
+type PasswordResetUserStore interface {
+	ListByIDs(ctx context.Context, ids []uint) ([]*models.User, error)
+	MarkPasswordResetRequired(ctx context.Context, ids []uint, requestedAt time.Time) error
+}
+
+// EmailQueuer 邮件入队，由 email.EmailService 和 email.GlobalQueue 实现
+type EmailQueuer interface {
+	QueueEmail(item *email.EmailQueue) error
+}
+
+// PasswordResetOptions 批量重置选项
+type PasswordResetOptions struct {
+	AppName       string        // 邮件中显示的应用名称
+	ResetURL      string        // 邮件中的重置密码页面地址
+	BatchSize     int           // 每批处理的用户数
+	BatchInterval time.Duration // 邮件批次间隔
+	MaxUsers      int           // 单次任务最多用户数
+}
+
+// PasswordResetOptionsFromConfig 从配置生成批量重置选项
+func PasswordResetOptionsFromConfig(appName string, cfg config.ForcedPasswordResetConfig) PasswordResetOptions {
+	return PasswordResetOptions{
+		AppName:       appName,
+		ResetURL:      cfg.ResetURL,
+		BatchSize:     cfg.BatchSize,
+		BatchInterval: cfg.BatchInterval,
+		MaxUsers:      cfg.MaxUsers,
+	}
+}
+
+// BulkPasswordResetRequest 批量强制重置密码请求
+type BulkPasswordResetRequest struct {
+	UserIDs []uint `json:"user_ids"` // 需要重置密码的用户ID
+	Reason  string `json:"reason"`   // 重置原因，显示在邮件中
+}
+
+// PasswordResetJob 批量重置任务进度
+type PasswordResetJob struct {
+	ID            string     `json:"id"`                        // 任务ID
+	Status        string     `json:"status"`                    // running/completed
+	Reason        string     `json:"reason,omitempty"`          // 重置原因
+	RequestedBy   uint       `json:"requested_by"`              // 发起任务的管理员ID
+	Total         int        `json:"total"`                     // 去重后的用户数
+	Flagged       int        `json:"flagged"`                   // 已标记并吊销令牌的用户数
+	EmailsQueued  int        `json:"emails_queued"`             // 已加入邮件队列的重置邮件数
+	Failed        int        `json:"failed"`                    // 处理失败的用户数
+	FailedUserIDs []uint     `json:"failed_user_ids,omitempty"` // 处理失败的用户ID，可重新发起任务
+	StartedAt     time.Time  `json:"started_at"`                // 开始时间
+	FinishedAt    *time.Time `json:"finished_at,omitempty"`     // 结束时间
+}
+
+// passwordResetService 批量强制重置密码服务实现
+type passwordResetService struct {
+	users         PasswordResetUserStore
+	tokenStore    cache.TokenStore
+	refreshTokens cache.RefreshTokenStore
+	queue         EmailQueuer
+	options       PasswordResetOptions
+	logger        *zap.Logger
+	now           func() time.Time
+	sleep         func(time.Duration)
+
+	mu   sync.RWMutex
+	jobs map[string]*PasswordResetJob
+	wg   sync.WaitGroup
+}
+
+// NewPasswordResetService 创建批量强制重置密码服务，未配置的选项使用默认值
+//
+// refreshTokens 可为nil，未启用刷新令牌登记时仅依靠 tokenStore 按签发时间吊销
+func NewPasswordResetService(users PasswordResetUserStore, tokenStore cache.TokenStore, refreshTokens cache.RefreshTokenStore, queue EmailQueuer, options PasswordResetOptions, logger *zap.Logger) PasswordResetService {
+	if options.BatchSize <= 0 {
+		options.BatchSize = DefaultPasswordResetBatchSize
+	}
+	if options.BatchInterval < 0 {
+		options.BatchInterval = DefaultPasswordResetBatchInterval
+	}
+	if options.MaxUsers <= 0 {
+		options.MaxUsers = DefaultPasswordResetMaxUsers
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &passwordResetService{
+		users:         users,
+		tokenStore:    tokenStore,
+		refreshTokens: refreshTokens,
+		queue:         queue,
+		options:       options,
+		logger:        logger,
+		now:           time.Now,
+		sleep:         time.Sleep,
+		jobs:          make(map[string]*PasswordResetJob),
+	}
+}
+
+// StartBulkReset 创建批量重置任务并在后台执行，立即返回任务初始状态
+func (s *passwordResetService) StartBulkReset(ctx context.Context, adminID uint, req BulkPasswordResetRequest) (*PasswordResetJob, error) {
+	ids := uniqueUserIDs(req.UserIDs)
+	if len(ids) == 0 {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "用户ID列表不能为空")
+	}
+	if len(ids) > s.options.MaxUsers {
+		return nil, pkgErrors.WrapErrorf(pkgErrors.ErrInvalidInput, "单次最多重置%d个用户", s.options.MaxUsers)
+	}
+
+	jobID, err := newPasswordResetJobID()
+	if err != nil {
+		return nil, fmt.Errorf("生成任务ID失败: %w", err)
+	}
+	job := &PasswordResetJob{
+		ID:          jobID,
+		Status:      PasswordResetJobRunning,
+		Reason:      req.Reason,
+		RequestedBy: adminID,
+		Total:       len(ids),
+		StartedAt:   s.now(),
+	}
+
+	s.mu.Lock()
+	s.pruneJobsLocked(job.StartedAt)
+	s.jobs[job.ID] = job
+	snapshot := job.snapshot()
+	s.mu.Unlock()
+
+	s.logger.Info("Bulk password reset started",
+		zap.String("job_id", job.ID),
+		zap.Uint("admin_id", adminID),
+		zap.Int("total", job.Total),
+		zap.String("reason", req.Reason))
+
+	// 任务不随请求结束而取消；实例重启时未发送的邮件丢失，已完成的标记和吊销不受影响
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.run(context.WithoutCancel(ctx), job, ids)
+	}()
+	return snapshot, nil
+}
+
+// GetJob 查询批量重置任务进度
+func (s *passwordResetService) GetJob(_ context.Context, jobID string) (*PasswordResetJob, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	job, ok := s.jobs[jobID]
+	if !ok {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "重置任务不存在或已过期")
+	}
+	return job.snapshot(), nil
+}
+
+// run 执行批量重置：先全部标记并吊销令牌，再分批发送重置邮件
+func (s *passwordResetService) run(ctx context.Context, job *PasswordResetJob, ids []uint) {
+	requestedAt := s.now()
+
+	var flagged []*models.User
+	for start := 0; start < len(ids); start += s.options.BatchSize {
+		batch := ids[start:min(start+s.options.BatchSize, len(ids))]
+		flagged = append(flagged, s.flagBatch(ctx, job, batch, requestedAt)...)
+	}
+
+	for start := 0; start < len(flagged); start += s.options.BatchSize {
+		if start > 0 && s.options.BatchInterval > 0 {
+			s.sleep(s.options.BatchInterval)
+		}
+		s.notifyBatch(job, flagged[start:min(start+s.options.BatchSize, len(flagged))])
+	}
+
+	s.finish(job, PasswordResetJobCompleted)
+}
+
+// flagBatch 标记一批用户必须重置密码并吊销其令牌，返回处理成功的用户
+func (s *passwordResetService) flagBatch(ctx context.Context, job *PasswordResetJob, ids []uint, requestedAt time.Time) []*models.User {
+	users, err := s.users.ListByIDs(ctx, ids)
+	if err != nil {
+		s.logger.Error("Failed to load users for password reset",
+			zap.String("job_id", job.ID),
+			zap.Error(err))
+		s.recordFailures(job, ids...)
+		return nil
+	}
+
+	found := make(map[uint]bool, len(users))
+	foundIDs := make([]uint, 0, len(users))
+	for _, user := range users {
+		found[user.ID] = true
+		foundIDs = append(foundIDs, user.ID)
+	}
+	for _, id := range ids {
+		if !found[id] {
+			s.recordFailures(job, id)
+		}
+	}
+	if len(foundIDs) == 0 {
+		return nil
+	}
+
+	if err := s.users.MarkPasswordResetRequired(ctx, foundIDs, requestedAt); err != nil {
+		s.logger.Error("Failed to flag users for password reset",
+			zap.String("job_id", job.ID),
+			zap.Error(err))
+		s.recordFailures(job, foundIDs...)
+		return nil
+	}
+
+	flagged := make([]*models.User, 0, len(users))
+	for _, user := range users {
+		if err := s.tokenStore.RevokeUserTokens(ctx, uint64(user.ID), requestedAt); err != nil {
+			s.logger.Error("Failed to revoke user tokens",
+				zap.String("job_id", job.ID),
+				zap.Uint("user_id", user.ID),
+				zap.Error(err))
+			s.recordFailures(job, user.ID)
+			continue
+		}
+		// 已按签发时间吊销，清理刷新令牌登记失败不影响结果
+		if s.refreshTokens != nil {
+			if err := s.refreshTokens.RevokeAllForUser(ctx, uint64(user.ID)); err != nil {
+				s.logger.Warn("Failed to revoke registered refresh tokens",
+					zap.String("job_id", job.ID),
+					zap.Uint("user_id", user.ID),
+					zap.Error(err))
+			}
+		}
+		flagged = append(flagged, user)
+	}
+
+	s.mu.Lock()
+	job.Flagged += len(flagged)
+	s.mu.Unlock()
+	return flagged
+}
+
+// notifyBatch 将一批用户的重置邮件加入队列
+func (s *passwordResetService) notifyBatch(job *PasswordResetJob, users []*models.User) {
+	queued := 0
+	for _, user := range users {
+		err := s.queue.QueueEmail(&email.EmailQueue{
+			To:       []string{user.Email},
+			Template: email.TemplateForcedPasswordReset,
+			Variables: map[string]interface{}{
+				"app_name":  s.options.AppName,
+				"username":  user.Username,
+				"reason":    job.Reason,
+				"reset_url": s.options.ResetURL,
+			},
+			Language: user.PreferredLanguage(),
+			Priority: email.PriorityHigh,
+		})
+		if err != nil {
+			// 用户已被标记且令牌已吊销，邮件失败时仍可通过忘记密码自行重置
+			s.logger.Warn("Failed to queue password reset email",
+				zap.String("job_id", job.ID),
+				zap.Uint("user_id", user.ID),
+				zap.Error(err))
+			s.recordFailures(job, user.ID)
+			continue
+		}
+		queued++
+	}
+
+	s.mu.Lock()
+	job.EmailsQueued += queued
+	s.mu.Unlock()
+}
+
+// recordFailures 记录处理失败的用户
+func (s *passwordResetService) recordFailures(job *PasswordResetJob, ids ...uint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job.Failed += len(ids)
+	job.FailedUserIDs = append(job.FailedUserIDs, ids...)
+}
+
+// finish 结束任务
+func (s *passwordResetService) finish(job *PasswordResetJob, status string) {
+	finishedAt := s.now()
+
+	s.mu.Lock()
+	job.Status = status
+	job.FinishedAt = &finishedAt
+	snapshot := job.snapshot()
+	s.mu.Unlock()
+
+	s.logger.Info("Bulk password reset finished",
+		zap.String("job_id", snapshot.ID),
+		zap.String("status", snapshot.Status),
+		zap.Int("total", snapshot.Total),
+		zap.Int("flagged", snapshot.Flagged),
+		zap.Int("emails_queued", snapshot.EmailsQueued),
+		zap.Int("failed", snapshot.Failed))
+}
+
+// pruneJobsLocked 清理结束超过保留时间的任务，调用方需持有写锁
+func (s *passwordResetService) pruneJobsLocked(now time.Time) {
+	for id, job := range s.jobs {
+		if job.FinishedAt != nil && now.Sub(*job.FinishedAt) > passwordResetJobRetention {
+			delete(s.jobs, id)
+		}
+	}
+}
+
+// snapshot 复制任务进度，调用方需持有锁
+func (j *PasswordResetJob) snapshot() *PasswordResetJob {
+	copied := *j
+	copied.FailedUserIDs = append([]uint(nil), j.FailedUserIDs...)
+	return &copied
+}
+
+// uniqueUserIDs 去除重复和为0的用户ID，保持原有顺序
+func uniqueUserIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	result := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if id == 0 || seen[id] {
+			continue
+		}
+		seen[id] = true
+		result = append(result, id)
+	}
+	return result
+}
+
+// newPasswordResetJobID 生成随机任务ID
+func newPasswordResetJobID() (string, error) {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
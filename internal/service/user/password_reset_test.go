@@ -0,0 +1,152 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/cache"
+	"cloudpan/internal/pkg/email"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/repository/models"
+)
+
+// recordingEmailQueue 记录入队的邮件，指定收件人入队失败
+type recordingEmailQueue struct {
+	mu     sync.Mutex
+	queued []*email.EmailQueue
+	reject map[string]bool
+}
+
+func (q *recordingEmailQueue) QueueEmail(item *email.EmailQueue) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.reject[item.To[0]] {
+		return errors.New("email queue is full")
+	}
+	q.queued = append(q.queued, item)
+	return nil
+}
+
+var passwordResetTestNow = time.Date(2024, 7, 1, 9, 0, 0, 0, time.UTC)
+
+func newTestPasswordResetService(repo *MockUserRepository, store cache.TokenStore, refreshTokens cache.RefreshTokenStore, queue EmailQueuer) (*passwordResetService, *[]time.Duration) {
+	svc := NewPasswordResetService(repo, store, refreshTokens, queue, PasswordResetOptions{
+		AppName:       "CloudPan",
+		ResetURL:      "https://pan.example.com/forgot-password",
+		BatchSize:     2,
+		BatchInterval: time.Second,
+		MaxUsers:      5,
+	}, zap.NewNop()).(*passwordResetService)
+	svc.now = func() time.Time { return passwordResetTestNow }
+	slept := &[]time.Duration{}
+	svc.sleep = func(d time.Duration) { *slept = append(*slept, d) }
+	return svc, slept
+}
+
+func resetTestUser(id uint, mail string) *models.User {
+	user := createTestUserWithID(id)
+	user.Email = mail
+	return user
+}
+
+func TestPasswordResetService_StartBulkReset(t *testing.T) {
+	ctx := context.Background()
+	repo := new(MockUserRepository)
+	store := cache.NewMemoryTokenStore(0)
+	refreshTokens := cache.NewMemoryRefreshTokenStore()
+	require.NoError(t, refreshTokens.Issue(ctx, 1, "jti-1", time.Now().Add(time.Hour)))
+	queue := &recordingEmailQueue{reject: map[string]bool{"c@example.com": true}}
+	svc, slept := newTestPasswordResetService(repo, store, refreshTokens, queue)
+
+	english := "en-US"
+	userB := resetTestUser(2, "b@example.com")
+	userB.Preferences = []models.UserPreference{{Category: models.PreferenceCategoryUI, Key: models.PreferenceKeyLanguage, Value: &english}}
+	repo.On("ListByIDs", mock.Anything, []uint{1, 2}).
+		Return([]*models.User{resetTestUser(1, "a@example.com"), userB}, nil)
+	repo.On("ListByIDs", mock.Anything, []uint{3, 4}).
+		Return([]*models.User{resetTestUser(3, "c@example.com")}, nil)
+	repo.On("MarkPasswordResetRequired", mock.Anything, []uint{1, 2}, passwordResetTestNow).Return(nil)
+	repo.On("MarkPasswordResetRequired", mock.Anything, []uint{3}, passwordResetTestNow).Return(nil)
+
+	job, err := svc.StartBulkReset(ctx, 99, BulkPasswordResetRequest{
+		UserIDs: []uint{1, 2, 2, 0, 3, 4},
+		Reason:  "凭据泄露",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, PasswordResetJobRunning, job.Status)
+	assert.Equal(t, 4, job.Total)
+	svc.wg.Wait()
+
+	job, err = svc.GetJob(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, PasswordResetJobCompleted, job.Status)
+	assert.Equal(t, 3, job.Flagged)
+	assert.Equal(t, 2, job.EmailsQueued)
+	assert.Equal(t, 2, job.Failed)
+	assert.ElementsMatch(t, []uint{4, 3}, job.FailedUserIDs)
+	assert.NotNil(t, job.FinishedAt)
+
+	// 邮件入队失败的用户仍然被标记并吊销令牌
+	for _, id := range []uint64{1, 2, 3} {
+		revoked, err := cache.IsTokenRevoked(ctx, store, id, passwordResetTestNow.Add(-time.Minute))
+		require.NoError(t, err)
+		assert.True(t, revoked, "user %d", id)
+	}
+	assert.ErrorIs(t, refreshTokens.Rotate(ctx, 1, "jti-1", "jti-2", time.Now().Add(time.Hour)), cache.ErrRefreshTokenNotFound)
+
+	require.Len(t, queue.queued, 2)
+	assert.Equal(t, email.TemplateForcedPasswordReset, queue.queued[0].Template)
+	assert.Equal(t, "凭据泄露", queue.queued[0].Variables["reason"])
+	assert.Equal(t, "https://pan.example.com/forgot-password", queue.queued[0].Variables["reset_url"])
+	assert.Empty(t, queue.queued[0].Language)
+	assert.Equal(t, "en-US", queue.queued[1].Language, "按用户的语言偏好发送")
+	assert.Equal(t, []time.Duration{time.Second}, *slept)
+}
+
+func TestPasswordResetService_MarkFailure(t *testing.T) {
+	ctx := context.Background()
+	repo := new(MockUserRepository)
+	store := cache.NewMemoryTokenStore(0)
+	queue := &recordingEmailQueue{}
+	svc, _ := newTestPasswordResetService(repo, store, nil, queue)
+
+	repo.On("ListByIDs", mock.Anything, []uint{1}).Return([]*models.User{resetTestUser(1, "a@example.com")}, nil)
+	repo.On("MarkPasswordResetRequired", mock.Anything, []uint{1}, passwordResetTestNow).Return(errors.New("db down"))
+
+	job, err := svc.StartBulkReset(ctx, 99, BulkPasswordResetRequest{UserIDs: []uint{1}})
+	require.NoError(t, err)
+	svc.wg.Wait()
+
+	job, err = svc.GetJob(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, job.Flagged)
+	assert.Equal(t, []uint{1}, job.FailedUserIDs)
+	assert.Empty(t, queue.queued)
+
+	// 标记失败时不吊销令牌，避免用户被登出却未要求重置
+	revoked, err := cache.IsTokenRevoked(ctx, store, 1, passwordResetTestNow.Add(-time.Minute))
+	require.NoError(t, err)
+	assert.False(t, revoked)
+}
+
+func TestPasswordResetService_Validation(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newTestPasswordResetService(new(MockUserRepository), cache.NewMemoryTokenStore(time.Hour), nil, &recordingEmailQueue{})
+
+	_, err := svc.StartBulkReset(ctx, 99, BulkPasswordResetRequest{UserIDs: []uint{0}})
+	assert.True(t, pkgErrors.IsValidationError(err))
+
+	_, err = svc.StartBulkReset(ctx, 99, BulkPasswordResetRequest{UserIDs: []uint{1, 2, 3, 4, 5, 6}})
+	assert.True(t, pkgErrors.IsValidationError(err))
+
+	_, err = svc.GetJob(ctx, "missing")
+	assert.True(t, pkgErrors.IsNotFoundError(err))
+}
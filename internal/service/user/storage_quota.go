@@ -0,0 +1,256 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/repository/models"
+)
+
+// DefaultReservationCleanupBatch 每次清理最多删除的过期预留数
+const DefaultReservationCleanupBatch = 500
+
+// StorageReservationStore 上传存储空间预留的数据访问，由 userrepo.StorageReservationRepository 实现
+type StorageReservationStore interface {
+	Reserve(ctx context.Context, reservation *models.StorageReservation, now time.Time, check func(user *models.User, reserved int64) error) error
+	Commit(ctx context.Context, userID uint, uploadID string, size int64) error
+	Release(ctx context.Context, uploadID string) (bool, error)
+	SumActive(ctx context.Context, userID uint, now time.Time) (int64, error)
+	DeleteExpired(ctx context.Context, now time.Time, limit int) (int64, error)
+}
+
+// StorageUserReader 读取用户配额和已用空间，由 userrepo.UserRepository 实现
+type StorageUserReader interface {
+	GetByID(ctx context.Context, id uint) (*models.User, error)
+}
+
+// StorageUsage 用户存储空间使用情况
+type StorageUsage struct {
+	Quota        int64   `json:"quota"`         // 存储配额(字节)，0表示不限额
+	Used         int64   `json:"used"`          // 已使用空间
+	Reserved     int64   `json:"reserved"`      // 进行中的上传预留的空间
+	Available    int64   `json:"available"`     // 剩余可用空间，不限额时为-1
+	UsagePercent float64 `json:"usage_percent"` // 已使用和预留空间占配额的百分比
+	Unlimited    bool    `json:"unlimited"`     // 是否不限额
+}
+
+// newStorageUsage 根据用户记录和预留合计计算使用情况
+func newStorageUsage(user *models.User, reserved int64) *StorageUsage {
+	usage := &StorageUsage{
+		Quota:     user.StorageQuota,
+		Used:      user.StorageUsed,
+		Reserved:  reserved,
+		Available: -1,
+		Unlimited: user.StorageQuota <= 0,
+	}
+	if !usage.Unlimited {
+		usage.Available = max(usage.Quota-usage.Used-usage.Reserved, 0)
+		usage.UsagePercent = float64(usage.Used+usage.Reserved) / float64(usage.Quota) * 100
+	}
+	return usage
+}
+
+// fits 检查剩余空间能否容纳指定大小
+func (u *StorageUsage) fits(size int64) bool {
+	return u.Unlimited || u.Used+u.Reserved+size <= u.Quota
+}
+
+// QuotaExceededError 存储空间不足，携带当前使用情况供客户端展示
+type QuotaExceededError struct {
+	Usage    *StorageUsage // 当前使用情况
+	Required int64         // 本次需要的空间
+}
+
+// Error 返回错误信息
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("存储空间不足: 需要 %d 字节，剩余 %d 字节", e.Required, e.Usage.Available)
+}
+
+// Unwrap 归类为配额超出错误
+func (e *QuotaExceededError) Unwrap() error {
+	return pkgErrors.ErrQuotaExceeded
+}
+
+// QuotaDetails 返回响应中data字段的内容
+func (e *QuotaExceededError) QuotaDetails() interface{} {
+	return QuotaExceededDetails{StorageUsage: *e.Usage, Required: e.Required}
+}
+
+// QuotaExceededDetails 存储空间不足时响应data字段的内容
+type QuotaExceededDetails struct {
+	StorageUsage
+	Required int64 `json:"required"` // 本次需要的空间
+}
+
+// StorageQuotaService 存储配额记账服务接口
+//
+// 上传开始前按声明大小预留空间，已用空间和进行中的预留合计不超过配额，并发上传不会超额：
+// 1. 预留：申请上传时调用 Reserve，空间不足返回 *QuotaExceededError
+// 2. 提交：上传完成时调用 Commit，删除预留并按实际大小累计已用空间
+// 3. 释放：上传失败或过期时调用 Release，预留的空间立即可用
+//
+// 客户端中断后遗留的预留到期后不再占用配额，由 CleanupExpired 定期删除
+//
+// 使用示例：
+//
+//	service := NewStorageQuotaService(reservationRepo, userRepo, logger)
+//	err := service.Reserve(ctx, userID, uploadID, size, expiresAt)
+//	err = service.Commit(ctx, userID, uploadID, actualSize)
+//	usage, err := service.GetUsage(ctx, userID)
+type StorageQuotaService interface {
+	GetUsage(ctx context.Context, userID uint) (*StorageUsage, error)
+	Check(ctx context.Context, userID uint, size int64) error
+	Reserve(ctx context.Context, userID uint, uploadID string, size int64, expiresAt time.Time) error
+	Commit(ctx context.Context, userID uint, uploadID string, size int64) error
+	Release(ctx context.Context, uploadID string) error
+	CleanupExpired(ctx context.Context, limit int) (int64, error)
+}
+
+// storageQuotaService 存储配额记账服务实现
+type storageQuotaService struct {
+	store  StorageReservationStore
+	users  StorageUserReader
+	logger *zap.Logger
+	now    func() time.Time
+}
+
+// NewStorageQuotaService 创建存储配额记账服务
+func NewStorageQuotaService(store StorageReservationStore, users StorageUserReader, logger *zap.Logger) StorageQuotaService {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &storageQuotaService{
+		store:  store,
+		users:  users,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// GetUsage 查询用户的配额、已用空间和进行中的预留
+func (s *storageQuotaService) GetUsage(ctx context.Context, userID uint) (*StorageUsage, error) {
+	if userID == 0 {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "用户ID不能为空")
+	}
+	account, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "用户不存在")
+		}
+		return nil, fmt.Errorf("获取用户失败: %w", err)
+	}
+	reserved, err := s.store.SumActive(ctx, userID, s.now())
+	if err != nil {
+		return nil, err
+	}
+	return newStorageUsage(account, reserved), nil
+}
+
+// Check 检查剩余空间能否容纳指定大小，不登记预留
+//
+// 用于上传请求的快速拒绝，size 小于1时按1字节检查，即空间已满时拒绝
+func (s *storageQuotaService) Check(ctx context.Context, userID uint, size int64) error {
+	usage, err := s.GetUsage(ctx, userID)
+	if err != nil {
+		return err
+	}
+	size = max(size, 1)
+	if !usage.fits(size) {
+		return &QuotaExceededError{Usage: usage, Required: size}
+	}
+	return nil
+}
+
+// Reserve 为上传预留空间，同一上传重复预留时更新大小和过期时间
+func (s *storageQuotaService) Reserve(ctx context.Context, userID uint, uploadID string, size int64, expiresAt time.Time) error {
+	if userID == 0 || uploadID == "" {
+		return pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "用户ID和上传任务ID不能为空")
+	}
+	if size <= 0 {
+		return pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "预留空间必须大于0")
+	}
+
+	reservation := &models.StorageReservation{UserID: userID, UploadID: uploadID, Size: size, ExpiresAt: expiresAt}
+	err := s.store.Reserve(ctx, reservation, s.now(), func(account *models.User, reserved int64) error {
+		usage := newStorageUsage(account, reserved)
+		if !usage.fits(size) {
+			return &QuotaExceededError{Usage: usage, Required: size}
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return pkgErrors.WrapError(pkgErrors.ErrResourceNotFound, "用户不存在")
+		}
+		var quotaErr *QuotaExceededError
+		if errors.As(err, &quotaErr) {
+			s.logger.Info("Storage reservation rejected",
+				zap.Uint("user_id", userID),
+				zap.String("upload_id", uploadID),
+				zap.Int64("size", size),
+				zap.Int64("available", quotaErr.Usage.Available))
+		}
+		return err
+	}
+	return nil
+}
+
+// Commit 上传完成时删除预留并按实际大小累计已用空间
+//
+// 预留已过期被清理时仍累计实际大小，保证已用空间与文件一致
+func (s *storageQuotaService) Commit(ctx context.Context, userID uint, uploadID string, size int64) error {
+	if userID == 0 || uploadID == "" {
+		return pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "用户ID和上传任务ID不能为空")
+	}
+	return s.store.Commit(ctx, userID, uploadID, size)
+}
+
+// Release 上传失败或过期时释放预留，预留不存在时忽略
+func (s *storageQuotaService) Release(ctx context.Context, uploadID string) error {
+	if uploadID == "" {
+		return nil
+	}
+	_, err := s.store.Release(ctx, uploadID)
+	return err
+}
+
+// CleanupExpired 删除过期的预留，返回删除数量
+func (s *storageQuotaService) CleanupExpired(ctx context.Context, limit int) (int64, error) {
+	if limit <= 0 {
+		limit = DefaultReservationCleanupBatch
+	}
+	deleted, err := s.store.DeleteExpired(ctx, s.now(), limit)
+	if err != nil {
+		return 0, err
+	}
+	if deleted > 0 {
+		s.logger.Info("Expired storage reservations cleaned up", zap.Int64("count", deleted))
+	}
+	return deleted, nil
+}
+
+var (
+	defaultStorageQuotaMu      sync.RWMutex
+	defaultStorageQuotaService StorageQuotaService
+)
+
+// SetDefaultStorageQuotaService 设置全局存储配额记账服务，启动时由main调用
+func SetDefaultStorageQuotaService(service StorageQuotaService) {
+	defaultStorageQuotaMu.Lock()
+	defer defaultStorageQuotaMu.Unlock()
+	defaultStorageQuotaService = service
+}
+
+// DefaultStorageQuotaService 返回全局存储配额记账服务，未创建时返回nil
+func DefaultStorageQuotaService() StorageQuotaService {
+	defaultStorageQuotaMu.RLock()
+	defer defaultStorageQuotaMu.RUnlock()
+	return defaultStorageQuotaService
+}
@@ -0,0 +1,194 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/repository/models"
+)
+
+// memoryReservationStore 内存存储空间预留，行为与数据库仓库一致
+type memoryReservationStore struct {
+	users        map[uint]*models.User
+	reservations map[string]models.StorageReservation
+}
+
+func newMemoryReservationStore(users ...*models.User) *memoryReservationStore {
+	store := &memoryReservationStore{
+		users:        make(map[uint]*models.User),
+		reservations: make(map[string]models.StorageReservation),
+	}
+	for _, account := range users {
+		store.users[account.ID] = account
+	}
+	return store
+}
+
+func (s *memoryReservationStore) GetByID(_ context.Context, id uint) (*models.User, error) {
+	account, ok := s.users[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *account
+	return &copied, nil
+}
+
+func (s *memoryReservationStore) Reserve(_ context.Context, reservation *models.StorageReservation, now time.Time, check func(user *models.User, reserved int64) error) error {
+	account, ok := s.users[reservation.UserID]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	var reserved int64
+	for uploadID, existing := range s.reservations {
+		if existing.UserID == reservation.UserID && uploadID != reservation.UploadID && existing.ExpiresAt.After(now) {
+			reserved += existing.Size
+		}
+	}
+	if err := check(account, reserved); err != nil {
+		return err
+	}
+	s.reservations[reservation.UploadID] = *reservation
+	return nil
+}
+
+func (s *memoryReservationStore) Commit(_ context.Context, userID uint, uploadID string, size int64) error {
+	delete(s.reservations, uploadID)
+	s.users[userID].StorageUsed += size
+	return nil
+}
+
+func (s *memoryReservationStore) Release(_ context.Context, uploadID string) (bool, error) {
+	_, ok := s.reservations[uploadID]
+	delete(s.reservations, uploadID)
+	return ok, nil
+}
+
+func (s *memoryReservationStore) SumActive(_ context.Context, userID uint, now time.Time) (int64, error) {
+	var reserved int64
+	for _, reservation := range s.reservations {
+		if reservation.UserID == userID && reservation.ExpiresAt.After(now) {
+			reserved += reservation.Size
+		}
+	}
+	return reserved, nil
+}
+
+func (s *memoryReservationStore) DeleteExpired(_ context.Context, now time.Time, limit int) (int64, error) {
+	var deleted int64
+	for uploadID, reservation := range s.reservations {
+		if int(deleted) >= limit {
+			break
+		}
+		if !reservation.ExpiresAt.After(now) {
+			delete(s.reservations, uploadID)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func newTestStorageQuotaService(users ...*models.User) (*storageQuotaService, *memoryReservationStore) {
+	store := newMemoryReservationStore(users...)
+	service := NewStorageQuotaService(store, store, nil).(*storageQuotaService)
+	service.now = func() time.Time { return time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC) }
+	return service, store
+}
+
+func TestStorageQuotaService_Reserve(t *testing.T) {
+	ctx := context.Background()
+	newAccount := func() *models.User {
+		account := &models.User{StorageQuota: 1000, StorageUsed: 200}
+		account.ID = 7
+		return account
+	}
+
+	t.Run("预留合计不超过配额", func(t *testing.T) {
+		service, _ := newTestStorageQuotaService(newAccount())
+		expiresAt := service.now().Add(time.Hour)
+
+		require.NoError(t, service.Reserve(ctx, 7, "a", 500, expiresAt))
+		err := service.Reserve(ctx, 7, "b", 400, expiresAt)
+
+		var quotaErr *QuotaExceededError
+		require.ErrorAs(t, err, &quotaErr)
+		assert.True(t, errors.Is(err, pkgErrors.ErrQuotaExceeded))
+		assert.Equal(t, int64(400), quotaErr.Required)
+		assert.Equal(t, StorageUsage{Quota: 1000, Used: 200, Reserved: 500, Available: 300, UsagePercent: 70}, *quotaErr.Usage)
+
+		// 同一上传重复预留不与自身的旧预留合计
+		require.NoError(t, service.Reserve(ctx, 7, "a", 800, expiresAt))
+	})
+
+	t.Run("提交累计已用空间，释放后空间可用", func(t *testing.T) {
+		service, store := newTestStorageQuotaService(newAccount())
+		expiresAt := service.now().Add(time.Hour)
+
+		require.NoError(t, service.Reserve(ctx, 7, "a", 500, expiresAt))
+		require.NoError(t, service.Reserve(ctx, 7, "b", 300, expiresAt))
+		require.NoError(t, service.Commit(ctx, 7, "a", 450))
+		require.NoError(t, service.Release(ctx, "b"))
+
+		usage, err := service.GetUsage(ctx, 7)
+		require.NoError(t, err)
+		assert.Equal(t, int64(650), usage.Used)
+		assert.Equal(t, int64(0), usage.Reserved)
+		assert.Equal(t, int64(350), usage.Available)
+		assert.Empty(t, store.reservations)
+	})
+
+	t.Run("过期预留不占用配额并被清理", func(t *testing.T) {
+		service, store := newTestStorageQuotaService(newAccount())
+
+		require.NoError(t, service.Reserve(ctx, 7, "stale", 800, service.now().Add(-time.Minute)))
+		require.NoError(t, service.Reserve(ctx, 7, "fresh", 800, service.now().Add(time.Hour)))
+
+		deleted, err := service.CleanupExpired(ctx, 0)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), deleted)
+		assert.Contains(t, store.reservations, "fresh")
+	})
+
+	t.Run("不限额", func(t *testing.T) {
+		unlimited := &models.User{StorageUsed: 1 << 40}
+		unlimited.ID = 8
+		service, _ := newTestStorageQuotaService(unlimited)
+
+		require.NoError(t, service.Reserve(ctx, 8, "a", 1<<40, service.now().Add(time.Hour)))
+		usage, err := service.GetUsage(ctx, 8)
+		require.NoError(t, err)
+		assert.True(t, usage.Unlimited)
+		assert.Equal(t, int64(-1), usage.Available)
+	})
+
+	t.Run("用户不存在", func(t *testing.T) {
+		service, _ := newTestStorageQuotaService()
+
+		err := service.Reserve(ctx, 9, "a", 1, service.now().Add(time.Hour))
+		assert.True(t, errors.Is(err, pkgErrors.ErrResourceNotFound))
+	})
+}
+
+func TestStorageQuotaService_Check(t *testing.T) {
+	ctx := context.Background()
+	account := &models.User{StorageQuota: 1000, StorageUsed: 1000}
+	account.ID = 7
+	service, store := newTestStorageQuotaService(account)
+
+	// 空间已满时即使不声明大小也拒绝
+	err := service.Check(ctx, 7, 0)
+	var quotaErr *QuotaExceededError
+	require.ErrorAs(t, err, &quotaErr)
+	assert.Equal(t, int64(1), quotaErr.Required)
+	assert.Equal(t, QuotaExceededDetails{StorageUsage: *quotaErr.Usage, Required: 1}, quotaErr.QuotaDetails())
+
+	store.users[7].StorageUsed = 0
+	assert.NoError(t, service.Check(ctx, 7, 1000))
+	assert.Empty(t, store.reservations, "检查不登记预留")
+}
@@ -0,0 +1,408 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/repository/models"
+)
+
+// 两步验证相关错误
+var (
+	// ErrInvalidTwoFactorCode 验证码错误、已使用或备用码无效
+	ErrInvalidTwoFactorCode = errors.New("invalid two-factor code")
+	// ErrTwoFactorPasswordIncorrect 启用、关闭两步验证时确认的登录密码错误
+	ErrTwoFactorPasswordIncorrect = errors.New("two-factor password confirmation failed")
+)
+
+// 备用码参数
+const (
+	backupCodeCount   = 10
+	backupCodeLength  = 10
+	backupCodeCharset = "abcdefghjkmnpqrstuvwxyz23456789" // 去掉易混淆的 0/o、1/l/i
+)
+
+// TwoFactorStore 两步验证需要的登记数据访问，由 userrepo.TwoFactorRepository 实现
+type TwoFactorStore interface {
+	GetByUserID(ctx context.Context, userID uint) (*models.UserTwoFactor, error)
+	SavePending(ctx context.Context, record *models.UserTwoFactor) error
+	Enable(ctx context.Context, userID uint, backupCodes string, step int64, now time.Time) (bool, error)
+	Delete(ctx context.Context, userID uint) error
+	UseStep(ctx context.Context, userID uint, step int64) (bool, error)
+	ReplaceBackupCodes(ctx context.Context, userID uint, oldCodes, newCodes string) (bool, error)
+}
+
+// TOTPVerifier TOTP密钥生成和验证码校验，由 verification.VerificationService 实现
+type TOTPVerifier interface {
+	GenerateTOTPSecret() (string, error)
+	TOTPProvisioningURI(issuer, account, secret string) string
+	ValidateTOTPCode(secret, code string, lastUsedStep int64) (int64, error)
+}
+
+// PasswordValidator 登录密码校验，由 UserService 实现
+type PasswordValidator interface {
+	ValidatePassword(ctx context.Context, userID uint, password string) (bool, error)
+}
+
+// TwoFactorStatus 两步验证状态
+type TwoFactorStatus struct {
+	Enabled              bool       `json:"enabled"`                // 是否已启用
+	Pending              bool       `json:"pending"`                // 已生成密钥但尚未确认启用
+	EnabledAt            *time.Time `json:"enabled_at,omitempty"`   // 启用时间
+	BackupCodesRemaining int        `json:"backup_codes_remaining"` // 剩余可用备用码数量
+}
+
+// TwoFactorEnrollment 两步验证登记信息，用户用验证器App扫描二维码或手动输入密钥
+type TwoFactorEnrollment struct {
+	Secret          string `json:"secret"`           // Base32密钥
+	ProvisioningURI string `json:"provisioning_uri"` // otpauth:// 地址，用于生成二维码
+}
+
+// TwoFactorService 两步验证(TOTP)服务接口
+//
+// 启用流程：
+// 1. BeginEnrollment 确认登录密码后生成密钥，返回二维码地址，此时尚未启用
+// 2. Enable 用户输入验证器App上的验证码确认后启用，返回一次性展示的备用码
+//
+// 登录时 Verify 接受TOTP验证码或备用码：同一时间步的验证码只能使用一次，备用码使用后作废；
+// 备用码只保存SHA-256哈希，关闭两步验证或重新生成备用码需要确认登录密码
+//
+// 使用示例：
+//
+//	service := NewTwoFactorService(twoFactorRepo, verificationService, userService, appName, logger)
+//	enrollment, err := service.BeginEnrollment(ctx, userID, email, password)
+//	backupCodes, err := service.Enable(ctx, userID, code)
+//	err = service.Verify(ctx, userID, code)
+type TwoFactorService interface {
+	Status(ctx context.Context, userID uint) (*TwoFactorStatus, error)
+	IsEnabled(ctx context.Context, userID uint) (bool, error)
+	BeginEnrollment(ctx context.Context, userID uint, account, password string) (*TwoFactorEnrollment, error)
+	Enable(ctx context.Context, userID uint, code string) ([]string, error)
+	Disable(ctx context.Context, userID uint, password, code string) error
+	RegenerateBackupCodes(ctx context.Context, userID uint, password string) ([]string, error)
+	Verify(ctx context.Context, userID uint, code string) error
+}
+
+// twoFactorService 两步验证服务实现
+type twoFactorService struct {
+	store     TwoFactorStore
+	verifier  TOTPVerifier
+	passwords PasswordValidator
+	issuer    string
+	logger    *zap.Logger
+	now       func() time.Time
+}
+
+// NewTwoFactorService 创建两步验证服务，issuer 为验证器App中显示的应用名称
+func NewTwoFactorService(store TwoFactorStore, verifier TOTPVerifier, passwords PasswordValidator, issuer string, logger *zap.Logger) TwoFactorService {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &twoFactorService{
+		store:     store,
+		verifier:  verifier,
+		passwords: passwords,
+		issuer:    issuer,
+		logger:    logger,
+		now:       time.Now,
+	}
+}
+
+// Status 查询两步验证状态，未登记时返回未启用
+func (s *twoFactorService) Status(ctx context.Context, userID uint) (*TwoFactorStatus, error) {
+	record, err := s.find(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return &TwoFactorStatus{}, nil
+	}
+	return &TwoFactorStatus{
+		Enabled:              record.Enabled,
+		Pending:              !record.Enabled,
+		EnabledAt:            record.EnabledAt,
+		BackupCodesRemaining: len(record.BackupCodeHashes()),
+	}, nil
+}
+
+// IsEnabled 判断用户是否已启用两步验证，登录时调用
+func (s *twoFactorService) IsEnabled(ctx context.Context, userID uint) (bool, error) {
+	record, err := s.find(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return record != nil && record.Enabled, nil
+}
+
+// BeginEnrollment 确认登录密码后生成新密钥，覆盖之前未确认的登记
+func (s *twoFactorService) BeginEnrollment(ctx context.Context, userID uint, account, password string) (*TwoFactorEnrollment, error) {
+	if err := s.checkPassword(ctx, userID, password); err != nil {
+		return nil, err
+	}
+	record, err := s.find(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if record != nil && record.Enabled {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "两步验证已启用，请先关闭")
+	}
+
+	secret, err := s.verifier.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.SavePending(ctx, &models.UserTwoFactor{UserID: userID, Secret: secret}); err != nil {
+		return nil, err
+	}
+	return &TwoFactorEnrollment{
+		Secret:          secret,
+		ProvisioningURI: s.verifier.TOTPProvisioningURI(s.issuer, account, secret),
+	}, nil
+}
+
+// Enable 校验验证器App上的验证码后启用两步验证，返回只展示一次的备用码
+func (s *twoFactorService) Enable(ctx context.Context, userID uint, code string) ([]string, error) {
+	record, err := s.find(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "请先生成两步验证密钥")
+	}
+	if record.Enabled {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "两步验证已启用")
+	}
+	step, err := s.verifier.ValidateTOTPCode(record.Secret, code, record.LastUsedStep)
+	if err != nil {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes, hashes, err := generateBackupCodes()
+	if err != nil {
+		return nil, err
+	}
+	ok, err := s.store.Enable(ctx, userID, hashes, step, s.now())
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		// 并发请求已启用或登记被覆盖
+		return nil, pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "两步验证状态已变化，请刷新后重试")
+	}
+	s.logger.Info("Two-factor authentication enabled", zap.Uint("user_id", userID))
+	return codes, nil
+}
+
+// Disable 确认登录密码和验证码(或备用码)后关闭两步验证
+func (s *twoFactorService) Disable(ctx context.Context, userID uint, password, code string) error {
+	if err := s.checkPassword(ctx, userID, password); err != nil {
+		return err
+	}
+	if err := s.Verify(ctx, userID, code); err != nil {
+		return err
+	}
+	if err := s.store.Delete(ctx, userID); err != nil {
+		return err
+	}
+	s.logger.Info("Two-factor authentication disabled", zap.Uint("user_id", userID))
+	return nil
+}
+
+// RegenerateBackupCodes 确认登录密码后生成新的备用码，之前的备用码全部作废
+func (s *twoFactorService) RegenerateBackupCodes(ctx context.Context, userID uint, password string) ([]string, error) {
+	if err := s.checkPassword(ctx, userID, password); err != nil {
+		return nil, err
+	}
+	record, err := s.enabledRecord(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	codes, hashes, err := generateBackupCodes()
+	if err != nil {
+		return nil, err
+	}
+	ok, err := s.store.ReplaceBackupCodes(ctx, userID, record.BackupCodes, hashes)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "备用码已变化，请刷新后重试")
+	}
+	return codes, nil
+}
+
+// Verify 校验TOTP验证码或备用码
+//
+// 6位数字按TOTP验证码处理，通过后记录时间步防止重放；其他输入按备用码处理，匹配后从记录中移除。
+// 未启用两步验证时返回操作不允许错误，验证码无效时返回 ErrInvalidTwoFactorCode
+func (s *twoFactorService) Verify(ctx context.Context, userID uint, code string) error {
+	record, err := s.enabledRecord(ctx, userID)
+	if err != nil {
+		return err
+	}
+	code = strings.TrimSpace(code)
+	if isTOTPCode(code) {
+		step, err := s.verifier.ValidateTOTPCode(record.Secret, code, record.LastUsedStep)
+		if err != nil {
+			return ErrInvalidTwoFactorCode
+		}
+		ok, err := s.store.UseStep(ctx, userID, step)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			// 同一验证码被并发使用
+			return ErrInvalidTwoFactorCode
+		}
+		return nil
+	}
+	return s.useBackupCode(ctx, record, code)
+}
+
+// useBackupCode 匹配并作废备用码
+func (s *twoFactorService) useBackupCode(ctx context.Context, record *models.UserTwoFactor, code string) error {
+	target := hashBackupCode(code)
+	hashes := record.BackupCodeHashes()
+	remaining := make([]string, 0, len(hashes))
+	matched := false
+	for _, hash := range hashes {
+		if !matched && subtle.ConstantTimeCompare([]byte(hash), []byte(target)) == 1 {
+			matched = true
+			continue
+		}
+		remaining = append(remaining, hash)
+	}
+	if !matched {
+		return ErrInvalidTwoFactorCode
+	}
+
+	ok, err := s.store.ReplaceBackupCodes(ctx, record.UserID, record.BackupCodes, strings.Join(remaining, ","))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		// 备用码已被并发请求使用或重新生成
+		return ErrInvalidTwoFactorCode
+	}
+	s.logger.Info("Two-factor backup code used",
+		zap.Uint("user_id", record.UserID),
+		zap.Int("remaining", len(remaining)))
+	return nil
+}
+
+// find 查询登记记录，不存在时返回nil
+func (s *twoFactorService) find(ctx context.Context, userID uint) (*models.UserTwoFactor, error) {
+	record, err := s.store.GetByUserID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("查询两步验证登记失败: %w", err)
+	}
+	return record, nil
+}
+
+// enabledRecord 查询已启用的登记记录
+func (s *twoFactorService) enabledRecord(ctx context.Context, userID uint) (*models.UserTwoFactor, error) {
+	record, err := s.find(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil || !record.Enabled {
+		return nil, pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "未启用两步验证")
+	}
+	return record, nil
+}
+
+// checkPassword 确认登录密码
+func (s *twoFactorService) checkPassword(ctx context.Context, userID uint, password string) error {
+	if password == "" {
+		return pkgErrors.WrapError(pkgErrors.ErrMissingRequired, "请输入登录密码")
+	}
+	ok, err := s.passwords.ValidatePassword(ctx, userID, password)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrTwoFactorPasswordIncorrect
+	}
+	return nil
+}
+
+// isTOTPCode 判断输入是否为6位数字验证码
+func isTOTPCode(code string) bool {
+	if len(code) != 6 {
+		return false
+	}
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// generateBackupCodes 生成备用码，返回明文(格式 xxxxx-xxxxx)和逗号分隔的哈希
+func generateBackupCodes() ([]string, string, error) {
+	codes := make([]string, backupCodeCount)
+	hashes := make([]string, backupCodeCount)
+	charsetSize := big.NewInt(int64(len(backupCodeCharset)))
+	for i := range codes {
+		raw := make([]byte, backupCodeLength)
+		for j := range raw {
+			n, err := rand.Int(rand.Reader, charsetSize)
+			if err != nil {
+				return nil, "", fmt.Errorf("生成备用码失败: %w", err)
+			}
+			raw[j] = backupCodeCharset[n.Int64()]
+		}
+		half := backupCodeLength / 2
+		codes[i] = string(raw[:half]) + "-" + string(raw[half:])
+		hashes[i] = hashBackupCode(codes[i])
+	}
+	return codes, strings.Join(hashes, ","), nil
+}
+
+// hashBackupCode 计算备用码哈希，忽略大小写、空格和连字符
+func hashBackupCode(code string) string {
+	normalized := strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(code)))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+var (
+	defaultTwoFactorMu      sync.RWMutex
+	defaultTwoFactorService TwoFactorService
+)
+
+// SetDefaultTwoFactorService 设置全局两步验证服务，启动时由main调用
+func SetDefaultTwoFactorService(service TwoFactorService) {
+	defaultTwoFactorMu.Lock()
+	defer defaultTwoFactorMu.Unlock()
+	defaultTwoFactorService = service
+}
+
+// DefaultTwoFactorService 返回全局两步验证服务，未创建时返回nil
+func DefaultTwoFactorService() TwoFactorService {
+	defaultTwoFactorMu.RLock()
+	defer defaultTwoFactorMu.RUnlock()
+	return defaultTwoFactorService
+}
@@ -0,0 +1,229 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+)
+
+// memoryTwoFactorStore 内存两步验证登记存储，行为与数据库仓库一致
+type memoryTwoFactorStore struct {
+	records map[uint]models.UserTwoFactor
+}
+
+func (s *memoryTwoFactorStore) GetByUserID(_ context.Context, userID uint) (*models.UserTwoFactor, error) {
+	record, ok := s.records[userID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &record, nil
+}
+
+func (s *memoryTwoFactorStore) SavePending(_ context.Context, record *models.UserTwoFactor) error {
+	s.records[record.UserID] = models.UserTwoFactor{UserID: record.UserID, Secret: record.Secret}
+	return nil
+}
+
+func (s *memoryTwoFactorStore) Enable(_ context.Context, userID uint, backupCodes string, step int64, now time.Time) (bool, error) {
+	record, ok := s.records[userID]
+	if !ok || record.Enabled {
+		return false, nil
+	}
+	record.Enabled = true
+	record.EnabledAt = &now
+	record.LastUsedStep = step
+	record.BackupCodes = backupCodes
+	s.records[userID] = record
+	return true, nil
+}
+
+func (s *memoryTwoFactorStore) Delete(_ context.Context, userID uint) error {
+	delete(s.records, userID)
+	return nil
+}
+
+func (s *memoryTwoFactorStore) UseStep(_ context.Context, userID uint, step int64) (bool, error) {
+	record, ok := s.records[userID]
+	if !ok || !record.Enabled || record.LastUsedStep >= step {
+		return false, nil
+	}
+	record.LastUsedStep = step
+	s.records[userID] = record
+	return true, nil
+}
+
+func (s *memoryTwoFactorStore) ReplaceBackupCodes(_ context.Context, userID uint, oldCodes, newCodes string) (bool, error) {
+	record, ok := s.records[userID]
+	if !ok || !record.Enabled || record.BackupCodes != oldCodes {
+		return false, nil
+	}
+	record.BackupCodes = newCodes
+	s.records[userID] = record
+	return true, nil
+}
+
+// clockTOTPVerifier 使用固定时间校验TOTP验证码
+type clockTOTPVerifier struct {
+	now time.Time
+}
+
+func (v *clockTOTPVerifier) GenerateTOTPSecret() (string, error) {
+	return utils.GenerateTOTPSecret()
+}
+
+func (v *clockTOTPVerifier) TOTPProvisioningURI(issuer, account, secret string) string {
+	return utils.TOTPProvisioningURI(issuer, account, secret)
+}
+
+func (v *clockTOTPVerifier) ValidateTOTPCode(secret, code string, lastUsedStep int64) (int64, error) {
+	step, ok := utils.ValidateTOTP(secret, code, v.now, 1)
+	if !ok || step <= lastUsedStep {
+		return 0, pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "验证码错误或已使用")
+	}
+	return step, nil
+}
+
+// staticPasswordValidator 只接受指定密码
+type staticPasswordValidator struct {
+	password string
+}
+
+func (v staticPasswordValidator) ValidatePassword(_ context.Context, _ uint, password string) (bool, error) {
+	return password == v.password, nil
+}
+
+func newTestTwoFactorService() (*twoFactorService, *memoryTwoFactorStore, *clockTOTPVerifier) {
+	store := &memoryTwoFactorStore{records: make(map[uint]models.UserTwoFactor)}
+	verifier := &clockTOTPVerifier{now: time.Date(2024, 7, 1, 9, 0, 0, 0, time.UTC)}
+	svc := NewTwoFactorService(store, verifier, staticPasswordValidator{password: "Secret123!"}, "CloudPan", nil).(*twoFactorService)
+	svc.now = func() time.Time { return verifier.now }
+	return svc, store, verifier
+}
+
+// currentCode 返回验证器当前时间的验证码
+func currentCode(v *clockTOTPVerifier, secret string) string {
+	code, err := utils.TOTPCode(secret, utils.TOTPStep(v.now))
+	if err != nil {
+		panic(err)
+	}
+	return code
+}
+
+// enrollTestUser 完成登记和启用，返回密钥和备用码
+func enrollTestUser(t *testing.T, svc *twoFactorService, verifier *clockTOTPVerifier, userID uint) (string, []string) {
+	t.Helper()
+	enrollment, err := svc.BeginEnrollment(context.Background(), userID, "alice@example.com", "Secret123!")
+	require.NoError(t, err)
+	codes, err := svc.Enable(context.Background(), userID, currentCode(verifier, enrollment.Secret))
+	require.NoError(t, err)
+	return enrollment.Secret, codes
+}
+
+func TestTwoFactorService_Enrollment(t *testing.T) {
+	ctx := context.Background()
+	svc, store, verifier := newTestTwoFactorService()
+
+	_, err := svc.BeginEnrollment(ctx, 1, "alice@example.com", "wrong")
+	assert.ErrorIs(t, err, ErrTwoFactorPasswordIncorrect)
+
+	enrollment, err := svc.BeginEnrollment(ctx, 1, "alice@example.com", "Secret123!")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(enrollment.ProvisioningURI, "otpauth://totp/CloudPan:alice@example.com?"))
+	assert.Contains(t, enrollment.ProvisioningURI, "secret="+enrollment.Secret)
+
+	// 确认前尚未启用
+	enabled, err := svc.IsEnabled(ctx, 1)
+	require.NoError(t, err)
+	assert.False(t, enabled)
+	status, err := svc.Status(ctx, 1)
+	require.NoError(t, err)
+	assert.True(t, status.Pending)
+
+	_, err = svc.Enable(ctx, 1, "000000")
+	assert.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+
+	codes, err := svc.Enable(ctx, 1, currentCode(verifier, enrollment.Secret))
+	require.NoError(t, err)
+	require.Len(t, codes, backupCodeCount)
+	assert.Regexp(t, `^[a-z2-9]{5}-[a-z2-9]{5}$`, codes[0])
+
+	// 只保存备用码哈希
+	record := store.records[1]
+	assert.NotContains(t, record.BackupCodes, codes[0])
+	assert.Len(t, record.BackupCodeHashes(), backupCodeCount)
+
+	status, err = svc.Status(ctx, 1)
+	require.NoError(t, err)
+	assert.True(t, status.Enabled)
+	assert.False(t, status.Pending)
+	assert.Equal(t, backupCodeCount, status.BackupCodesRemaining)
+
+	// 已启用时不能重新登记
+	_, err = svc.BeginEnrollment(ctx, 1, "alice@example.com", "Secret123!")
+	assert.True(t, errors.Is(err, pkgErrors.ErrOperationNotAllowed))
+}
+
+func TestTwoFactorService_VerifyTOTP(t *testing.T) {
+	ctx := context.Background()
+	svc, _, verifier := newTestTwoFactorService()
+	secret, _ := enrollTestUser(t, svc, verifier, 1)
+
+	// 启用时使用的验证码不能再次用于登录
+	assert.ErrorIs(t, svc.Verify(ctx, 1, currentCode(verifier, secret)), ErrInvalidTwoFactorCode)
+
+	verifier.now = verifier.now.Add(utils.TOTPPeriod)
+	code := currentCode(verifier, secret)
+	require.NoError(t, svc.Verify(ctx, 1, " "+code+" "))
+	assert.ErrorIs(t, svc.Verify(ctx, 1, code), ErrInvalidTwoFactorCode)
+
+	// 未启用的用户
+	assert.True(t, errors.Is(svc.Verify(ctx, 2, code), pkgErrors.ErrOperationNotAllowed))
+}
+
+func TestTwoFactorService_BackupCodes(t *testing.T) {
+	ctx := context.Background()
+	svc, _, verifier := newTestTwoFactorService()
+	_, codes := enrollTestUser(t, svc, verifier, 1)
+
+	// 备用码忽略大小写和连字符，且只能使用一次
+	require.NoError(t, svc.Verify(ctx, 1, strings.ToUpper(strings.ReplaceAll(codes[0], "-", ""))))
+	assert.ErrorIs(t, svc.Verify(ctx, 1, codes[0]), ErrInvalidTwoFactorCode)
+	status, err := svc.Status(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, backupCodeCount-1, status.BackupCodesRemaining)
+
+	_, err = svc.RegenerateBackupCodes(ctx, 1, "wrong")
+	assert.ErrorIs(t, err, ErrTwoFactorPasswordIncorrect)
+	fresh, err := svc.RegenerateBackupCodes(ctx, 1, "Secret123!")
+	require.NoError(t, err)
+	require.Len(t, fresh, backupCodeCount)
+
+	// 旧备用码作废
+	assert.ErrorIs(t, svc.Verify(ctx, 1, codes[1]), ErrInvalidTwoFactorCode)
+	require.NoError(t, svc.Verify(ctx, 1, fresh[1]))
+}
+
+func TestTwoFactorService_Disable(t *testing.T) {
+	ctx := context.Background()
+	svc, store, verifier := newTestTwoFactorService()
+	_, codes := enrollTestUser(t, svc, verifier, 1)
+
+	assert.ErrorIs(t, svc.Disable(ctx, 1, "wrong", codes[0]), ErrTwoFactorPasswordIncorrect)
+	assert.ErrorIs(t, svc.Disable(ctx, 1, "Secret123!", "abcde-fghij"), ErrInvalidTwoFactorCode)
+
+	require.NoError(t, svc.Disable(ctx, 1, "Secret123!", codes[0]))
+	assert.Empty(t, store.records)
+	enabled, err := svc.IsEnabled(ctx, 1)
+	require.NoError(t, err)
+	assert.False(t, enabled)
+}
@@ -40,6 +40,7 @@ type UserService interface {
 	ActivateUser(ctx context.Context, userID uint) error
 	DeactivateUser(ctx context.Context, userID uint) error
 	SuspendUser(ctx context.Context, userID uint, reason string) error
+	CancelDeletion(ctx context.Context, userID uint) error
 	VerifyEmail(ctx context.Context, userID uint) error
 	VerifyPhone(ctx context.Context, userID uint) error
 
@@ -50,6 +51,7 @@ type UserService interface {
 
 	// 存储配额管理
 	UpdateStorageUsed(ctx context.Context, userID uint, size int64) error
+	UpdateStorageQuota(ctx context.Context, userID uint, quota int64) error
 	CheckStorageQuota(ctx context.Context, userID uint, requiredSize int64) (bool, error)
 	GetStorageStats(ctx context.Context, userID uint) (*UserStorageStats, error)
 
@@ -2,12 +2,16 @@ package user
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"gorm.io/gorm"
 
 	"cloudpan/internal/pkg/cache"
+	"cloudpan/internal/pkg/database"
+	pkgErrors "cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/tracing"
 	"cloudpan/internal/repository/models"
 	userrepo "cloudpan/internal/repository/user"
 )
@@ -19,7 +23,7 @@ type userService struct {
 	db           *gorm.DB
 }
 
-// NewUserService 创建用户服务实例
+// NewUserService 创建用户服务实例，cacheManager为nil时不使用缓存
 func NewUserService(userRepo userrepo.UserRepository, cacheManager *cache.CacheManager, db *gorm.DB) UserService {
 	return &userService{
 		userRepo:     userRepo,
@@ -29,7 +33,10 @@ func NewUserService(userRepo userrepo.UserRepository, cacheManager *cache.CacheM
 }
 
 // CreateUser 创建用户
-func (s *userService) CreateUser(ctx context.Context, user *models.User) error {
+func (s *userService) CreateUser(ctx context.Context, user *models.User) (err error) {
+	ctx, span := tracing.Start(ctx, "UserService.CreateUser")
+	defer tracing.Finish(span, &err)
+
 	if user == nil {
 		return fmt.Errorf("用户数据不能为空")
 	}
@@ -64,7 +71,10 @@ func (s *userService) CreateUser(ctx context.Context, user *models.User) error {
 }
 
 // GetUserByID 根据ID获取用户
-func (s *userService) GetUserByID(ctx context.Context, id uint) (*models.User, error) {
+func (s *userService) GetUserByID(ctx context.Context, id uint) (_ *models.User, err error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetUserByID")
+	defer tracing.Finish(span, &err)
+
 	if id == 0 {
 		return nil, fmt.Errorf("用户ID不能为空")
 	}
@@ -88,7 +98,10 @@ func (s *userService) GetUserByID(ctx context.Context, id uint) (*models.User, e
 }
 
 // GetUserByUUID 根据UUID获取用户
-func (s *userService) GetUserByUUID(ctx context.Context, uuid string) (*models.User, error) {
+func (s *userService) GetUserByUUID(ctx context.Context, uuid string) (_ *models.User, err error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetUserByUUID")
+	defer tracing.Finish(span, &err)
+
 	if uuid == "" {
 		return nil, fmt.Errorf("用户UUID不能为空")
 	}
@@ -112,7 +125,10 @@ func (s *userService) GetUserByUUID(ctx context.Context, uuid string) (*models.U
 }
 
 // GetUserByEmail 根据邮箱获取用户
-func (s *userService) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+func (s *userService) GetUserByEmail(ctx context.Context, email string) (_ *models.User, err error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetUserByEmail")
+	defer tracing.Finish(span, &err)
+
 	if email == "" {
 		return nil, fmt.Errorf("邮箱不能为空")
 	}
@@ -136,7 +152,10 @@ func (s *userService) GetUserByEmail(ctx context.Context, email string) (*models
 }
 
 // GetUserByUsername 根据用户名获取用户
-func (s *userService) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+func (s *userService) GetUserByUsername(ctx context.Context, username string) (_ *models.User, err error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetUserByUsername")
+	defer tracing.Finish(span, &err)
+
 	if username == "" {
 		return nil, fmt.Errorf("用户名不能为空")
 	}
@@ -160,7 +179,10 @@ func (s *userService) GetUserByUsername(ctx context.Context, username string) (*
 }
 
 // UpdateUser 更新用户信息
-func (s *userService) UpdateUser(ctx context.Context, user *models.User) error {
+func (s *userService) UpdateUser(ctx context.Context, user *models.User) (err error) {
+	ctx, span := tracing.Start(ctx, "UserService.UpdateUser")
+	defer tracing.Finish(span, &err)
+
 	if user == nil || user.ID == 0 {
 		return fmt.Errorf("用户数据不能为空")
 	}
@@ -172,7 +194,7 @@ func (s *userService) UpdateUser(ctx context.Context, user *models.User) error {
 
 	// 清除相关缓存
 	s.clearUserCache(ctx, user.Email, user.Username, user.UUID)
-	if err := s.cacheManager.Delete(fmt.Sprintf("user:id:%d", user.ID)); err != nil {
+	if err := s.deleteCache(ctx, fmt.Sprintf("user:id:%d", user.ID)); err != nil {
 		// 缓存删除失败，记录错误但不影响主流程
 		_ = err // 明确忽略错误
 	}
@@ -199,7 +221,7 @@ func (s *userService) DeleteUser(ctx context.Context, id uint) error {
 
 	// 清除相关缓存
 	s.clearUserCache(ctx, user.Email, user.Username, user.UUID)
-	if err := s.cacheManager.Delete(fmt.Sprintf("user:id:%d", id)); err != nil {
+	if err := s.deleteCache(ctx, fmt.Sprintf("user:id:%d", id)); err != nil {
 		// 缓存删除失败，记录错误但不影响主流程
 		_ = err // 明确忽略错误
 	}
@@ -208,7 +230,10 @@ func (s *userService) DeleteUser(ctx context.Context, id uint) error {
 }
 
 // CheckUserExists 检查用户是否存在（邮箱或用户名）
-func (s *userService) CheckUserExists(ctx context.Context, email, username string) (bool, error) {
+func (s *userService) CheckUserExists(ctx context.Context, email, username string) (_ bool, err error) {
+	ctx, span := tracing.Start(ctx, "UserService.CheckUserExists")
+	defer tracing.Finish(span, &err)
+
 	if email == "" && username == "" {
 		return false, fmt.Errorf("邮箱和用户名不能同时为空")
 	}
@@ -239,7 +264,10 @@ func (s *userService) CheckUserExists(ctx context.Context, email, username strin
 }
 
 // CheckEmailExists 检查邮箱是否存在
-func (s *userService) CheckEmailExists(ctx context.Context, email string) (bool, error) {
+func (s *userService) CheckEmailExists(ctx context.Context, email string) (_ bool, err error) {
+	ctx, span := tracing.Start(ctx, "UserService.CheckEmailExists")
+	defer tracing.Finish(span, &err)
+
 	if email == "" {
 		return false, fmt.Errorf("邮箱不能为空")
 	}
@@ -247,7 +275,7 @@ func (s *userService) CheckEmailExists(ctx context.Context, email string) (bool,
 	// 尝试从缓存获取
 	cacheKey := fmt.Sprintf("user_exists:email:%s", email)
 	var cached string
-	if err := s.cacheManager.Get(cacheKey, &cached); err == nil {
+	if err := s.getCache(ctx, cacheKey, &cached); err == nil {
 		return cached == "true", nil
 	}
 
@@ -262,7 +290,7 @@ func (s *userService) CheckEmailExists(ctx context.Context, email string) (bool,
 	if exists {
 		existsStr = "true"
 	}
-	if err := s.cacheManager.SetWithTTL(cacheKey, existsStr, 5*time.Minute); err != nil {
+	if err := s.setCache(ctx, cacheKey, existsStr, 5*time.Minute); err != nil {
 		// 缓存设置失败，记录错误但不影响主流程
 		_ = err // 明确忽略错误
 	}
@@ -271,7 +299,10 @@ func (s *userService) CheckEmailExists(ctx context.Context, email string) (bool,
 }
 
 // CheckUsernameExists 检查用户名是否存在
-func (s *userService) CheckUsernameExists(ctx context.Context, username string) (bool, error) {
+func (s *userService) CheckUsernameExists(ctx context.Context, username string) (_ bool, err error) {
+	ctx, span := tracing.Start(ctx, "UserService.CheckUsernameExists")
+	defer tracing.Finish(span, &err)
+
 	if username == "" {
 		return false, fmt.Errorf("用户名不能为空")
 	}
@@ -279,7 +310,7 @@ func (s *userService) CheckUsernameExists(ctx context.Context, username string)
 	// 尝试从缓存获取
 	cacheKey := fmt.Sprintf("user_exists:username:%s", username)
 	var cached string
-	if err := s.cacheManager.Get(cacheKey, &cached); err == nil {
+	if err := s.getCache(ctx, cacheKey, &cached); err == nil {
 		return cached == "true", nil
 	}
 
@@ -294,7 +325,7 @@ func (s *userService) CheckUsernameExists(ctx context.Context, username string)
 	if exists {
 		existsStr = "true"
 	}
-	if err := s.cacheManager.SetWithTTL(cacheKey, existsStr, 5*time.Minute); err != nil {
+	if err := s.setCache(ctx, cacheKey, existsStr, 5*time.Minute); err != nil {
 		// 缓存设置失败，记录错误但不影响主流程
 		_ = err // 明确忽略错误
 	}
@@ -325,8 +356,11 @@ func (s *userService) UpdatePassword(ctx context.Context, userID uint, hashedPas
 		return fmt.Errorf("密码哈希值不能为空")
 	}
 
-	// 直接更新数据库中的密码字段
-	result := s.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).Update("password_hash", hashedPassword)
+	// 直接更新数据库中的密码字段，设置新密码后解除强制重置标记
+	result := database.Conn(ctx, s.db).Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"password_hash":           hashedPassword,
+		"password_reset_required": false,
+	})
 	if result.Error != nil {
 		return fmt.Errorf("更新密码失败: %w", result.Error)
 	}
@@ -339,7 +373,7 @@ func (s *userService) UpdatePassword(ctx context.Context, userID uint, hashedPas
 	user, err := s.GetUserByID(ctx, userID)
 	if err == nil {
 		s.clearUserCache(ctx, user.Email, user.Username, user.UUID)
-		if err := s.cacheManager.Delete(fmt.Sprintf("user:id:%d", userID)); err != nil {
+		if err := s.deleteCache(ctx, fmt.Sprintf("user:id:%d", userID)); err != nil {
 			// 缓存删除失败，记录错误但不影响主流程
 			_ = err // 明确忽略错误
 		}
@@ -363,6 +397,40 @@ func (s *userService) SuspendUser(ctx context.Context, userID uint, reason strin
 	return s.updateUserStatus(ctx, userID, "suspended")
 }
 
+// CancelDeletion 取消账户的计划删除并恢复为正常状态
+//
+// 只恢复仍在计划删除期内的账户；彻底删除任务按 deletion_scheduled_at 选取到期账户，
+// 条件更新保证与删除任务并发时账户要么被恢复、要么被删除
+func (s *userService) CancelDeletion(ctx context.Context, userID uint) error {
+	if userID == 0 {
+		return fmt.Errorf("用户ID不能为空")
+	}
+
+	result := database.Conn(ctx, s.db).Model(&models.User{}).
+		Where("id = ? AND status = ? AND deletion_scheduled_at > ?", userID, "deleted", time.Now()).
+		Updates(map[string]interface{}{
+			"status":                "active",
+			"deletion_scheduled_at": nil,
+		})
+	if result.Error != nil {
+		return fmt.Errorf("恢复账户失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return pkgErrors.WrapError(pkgErrors.ErrOperationNotAllowed, "账户未计划删除或已超过恢复期限")
+	}
+
+	// 清除用户相关缓存
+	if err := s.deleteCache(ctx, fmt.Sprintf("user:id:%d", userID)); err != nil {
+		// 缓存删除失败，记录错误但不影响主流程
+		_ = err // 明确忽略错误
+	}
+	if user, err := s.userRepo.GetByID(ctx, userID); err == nil {
+		s.clearUserCache(ctx, user.Email, user.Username, user.UUID)
+	}
+
+	return nil
+}
+
 // VerifyEmail 验证用户邮箱
 func (s *userService) VerifyEmail(ctx context.Context, userID uint) error {
 	if userID == 0 {
@@ -442,7 +510,7 @@ func (s *userService) GetActiveUsersCount(ctx context.Context) (int64, error) {
 	// 尝试从缓存获取
 	cacheKey := "stats:active_users_count"
 	var cached string
-	if err := s.cacheManager.Get(cacheKey, &cached); err == nil {
+	if err := s.getCache(ctx, cacheKey, &cached); err == nil {
 		return parseIntFromString(cached), nil
 	}
 
@@ -453,7 +521,7 @@ func (s *userService) GetActiveUsersCount(ctx context.Context) (int64, error) {
 	}
 
 	// 缓存结果
-	if err := s.cacheManager.SetWithTTL(cacheKey, fmt.Sprintf("%d", count), 1*time.Hour); err != nil {
+	if err := s.setCache(ctx, cacheKey, fmt.Sprintf("%d", count), 1*time.Hour); err != nil {
 		// 缓存设置失败，记录错误但不影响主流程
 		_ = err // 明确忽略错误
 	}
@@ -472,11 +540,43 @@ func (s *userService) UpdateStorageUsed(ctx context.Context, userID uint, size i
 	}
 
 	// 清除相关缓存
-	if err := s.cacheManager.Delete(fmt.Sprintf("user:id:%d", userID)); err != nil {
+	if err := s.deleteCache(ctx, fmt.Sprintf("user:id:%d", userID)); err != nil {
 		// 缓存删除失败，记录错误但不影响主流程
 		_ = err // 明确忽略错误
 	}
-	if err := s.cacheManager.Delete(fmt.Sprintf("storage_stats:%d", userID)); err != nil {
+	if err := s.deleteCache(ctx, fmt.Sprintf("storage_stats:%d", userID)); err != nil {
+		// 缓存删除失败，记录错误但不影响主流程
+		_ = err // 明确忽略错误
+	}
+
+	return nil
+}
+
+// UpdateStorageQuota 调整用户存储配额，0表示不限制
+//
+// 只更新 storage_quota 列，避免覆盖上传过程中并发更新的存储使用量
+func (s *userService) UpdateStorageQuota(ctx context.Context, userID uint, quota int64) error {
+	if userID == 0 {
+		return fmt.Errorf("用户ID不能为空")
+	}
+	if quota < 0 {
+		return pkgErrors.WrapError(pkgErrors.ErrInvalidInput, "存储配额不能为负数")
+	}
+	if _, err := s.GetUserByID(ctx, userID); err != nil {
+		return fmt.Errorf("获取用户失败: %w", err)
+	}
+
+	if err := database.Conn(ctx, s.db).Model(&models.User{}).
+		Where("id = ?", userID).
+		UpdateColumn("storage_quota", quota).Error; err != nil {
+		return fmt.Errorf("更新存储配额失败: %w", err)
+	}
+
+	if err := s.deleteCache(ctx, fmt.Sprintf("user:id:%d", userID)); err != nil {
+		// 缓存删除失败，记录错误但不影响主流程
+		_ = err // 明确忽略错误
+	}
+	if err := s.deleteCache(ctx, fmt.Sprintf("storage_stats:%d", userID)); err != nil {
 		// 缓存删除失败，记录错误但不影响主流程
 		_ = err // 明确忽略错误
 	}
@@ -598,26 +698,53 @@ func (s *userService) updateUserStatus(ctx context.Context, userID uint, status
 	return s.UpdateUser(ctx, user)
 }
 
+// errCacheDisabled 未配置缓存时读取缓存返回的错误，按未命中处理
+var errCacheDisabled = errors.New("cache disabled")
+
+// getCache 读取缓存，未配置缓存时按未命中处理
+func (s *userService) getCache(ctx context.Context, key string, dest interface{}) error {
+	if s.cacheManager == nil {
+		return errCacheDisabled
+	}
+	return s.cacheManager.WithContext(ctx).Get(key, dest)
+}
+
+// setCache 写入缓存，未配置缓存时忽略
+func (s *userService) setCache(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	if s.cacheManager == nil {
+		return nil
+	}
+	return s.cacheManager.WithContext(ctx).SetWithTTL(key, value, ttl)
+}
+
+// deleteCache 删除缓存，未配置缓存时忽略
+func (s *userService) deleteCache(ctx context.Context, keys ...string) error {
+	if s.cacheManager == nil {
+		return nil
+	}
+	return s.cacheManager.WithContext(ctx).Delete(keys...)
+}
+
 // clearUserCache 清除用户相关缓存
-func (s *userService) clearUserCache(_ context.Context, email, username, uuid string) {
+func (s *userService) clearUserCache(ctx context.Context, email, username, uuid string) {
 	if email != "" {
-		if err := s.cacheManager.Delete(fmt.Sprintf("user:email:%s", email)); err != nil {
+		if err := s.deleteCache(ctx, fmt.Sprintf("user:email:%s", email)); err != nil {
 			_ = err // 明确忽略错误
 		}
-		if err := s.cacheManager.Delete(fmt.Sprintf("user_exists:email:%s", email)); err != nil {
+		if err := s.deleteCache(ctx, fmt.Sprintf("user_exists:email:%s", email)); err != nil {
 			_ = err // 明确忽略错误
 		}
 	}
 	if username != "" {
-		if err := s.cacheManager.Delete(fmt.Sprintf("user:username:%s", username)); err != nil {
+		if err := s.deleteCache(ctx, fmt.Sprintf("user:username:%s", username)); err != nil {
 			_ = err // 明确忽略错误
 		}
-		if err := s.cacheManager.Delete(fmt.Sprintf("user_exists:username:%s", username)); err != nil {
+		if err := s.deleteCache(ctx, fmt.Sprintf("user_exists:username:%s", username)); err != nil {
 			_ = err // 明确忽略错误
 		}
 	}
 	if uuid != "" {
-		if err := s.cacheManager.Delete(fmt.Sprintf("user:uuid:%s", uuid)); err != nil {
+		if err := s.deleteCache(ctx, fmt.Sprintf("user:uuid:%s", uuid)); err != nil {
 			_ = err // 明确忽略错误
 		}
 	}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -146,6 +147,40 @@ func (m *MockUserRepository) GetUsersByStatus(ctx context.Context, status string
 	return args.Get(0).([]*models.User), args.Get(1).(int64), args.Error(2)
 }
 
+func (m *MockUserRepository) ListByIDs(ctx context.Context, ids []uint) ([]*models.User, error) {
+	args := m.Called(ctx, ids)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.User), args.Error(1)
+}
+
+func (m *MockUserRepository) MarkPasswordResetRequired(ctx context.Context, ids []uint, requestedAt time.Time) error {
+	args := m.Called(ctx, ids, requestedAt)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) ListActiveRoleNames(ctx context.Context, userID uint) ([]string, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockUserRepository) ListActiveUserIDsByRole(ctx context.Context, roleName string) ([]uint, error) {
+	args := m.Called(ctx, roleName)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]uint), args.Error(1)
+}
+
+func (m *MockUserRepository) DeleteExpiredSessions(ctx context.Context, before time.Time, limit int) (int64, error) {
+	args := m.Called(ctx, before, limit)
+	return args.Get(0).(int64), args.Error(1)
+}
+
 // 测试辅助函数
 func createTestUser() *models.User {
 	return &models.User{
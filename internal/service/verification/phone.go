@@ -0,0 +1,208 @@
+package verification
+
+import (
+	"context"
+	stdErrors "errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+
+	"cloudpan/internal/pkg/cache"
+	"cloudpan/internal/pkg/config"
+	"cloudpan/internal/pkg/database"
+	"cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/sms"
+	"cloudpan/internal/pkg/tracing"
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+)
+
+// 手机验证码发送频率限制
+const (
+	DefaultPhoneHourlyLimit = 5         // 同一手机号每小时默认最多发送的条数
+	phoneSendWindow         = time.Hour // 发送次数统计窗口
+	phoneAttemptType        = "sms"     // 发送次数计数键中的类型，所有用途的短信共用一个计数
+)
+
+// PhoneOptions 手机验证码选项
+type PhoneOptions struct {
+	Sender             sms.Sender // 短信发送渠道，为nil时不支持手机验证码
+	DefaultCountryCode string     // 未带国家码的手机号使用的国家码
+	HourlyLimit        int        // 同一手机号每小时最多发送的条数，0使用 DefaultPhoneHourlyLimit
+}
+
+// PhoneOptionsFromConfig 从短信服务配置生成选项，sender 由 sms.FromConfig 创建
+func PhoneOptionsFromConfig(cfg config.SMSConfig, sender sms.Sender) PhoneOptions {
+	return PhoneOptions{
+		Sender:             sender,
+		DefaultCountryCode: cfg.DefaultCountryCode,
+		HourlyLimit:        cfg.HourlyLimit,
+	}
+}
+
+// phoneCounter 支持计数的验证码缓存，由 cache.CacheManager 实现
+type phoneCounter interface {
+	Increment(key string) (int64, error)
+	Expire(key string, ttl time.Duration) error
+}
+
+// GeneratePhoneCode 生成手机验证码并通过短信发送
+//
+// 手机号规范化为E.164格式后作为验证码目标，验证时同一号码的不同写法都能匹配。
+// 除与邮箱验证码相同的目标和IP频率限制外，同一号码每小时的发送条数另有上限，
+// 计数保存在验证码缓存的尝试次数键中，缓存不可用时按数据库记录统计。
+// 短信发送失败时作废本次验证码并返回错误，避免用户等待永远不会到达的短信
+func (s *verificationService) GeneratePhoneCode(ctx context.Context, phone, codeType string, userID *uint, ipAddress string) (_ *models.VerificationCode, err error) {
+	ctx, span := tracing.Start(ctx, "VerificationService.GeneratePhoneCode", attribute.String("verification.type", codeType))
+	defer tracing.Finish(span, &err)
+
+	options := s.phoneOptions()
+	if options.Sender == nil {
+		return nil, errors.NewValidationError("phone", "短信验证码未启用")
+	}
+	target, err := s.validatePhoneParams(phone, codeType, options)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.CheckRateLimit(ctx, target, codeType, ipAddress); err != nil {
+		return nil, err
+	}
+	if err := s.checkPhoneRateLimit(ctx, target, options); err != nil {
+		return nil, err
+	}
+
+	code, salt, err := s.generateCodeAndSalt(codeType)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.invalidateOldCodes(ctx, target, codeType); err != nil {
+		s.logger.Warn("Failed to invalidate old codes", zap.Error(err))
+	}
+	verificationCode, err := s.createAndSaveCode(ctx, target, codeType, code, salt, ipAddress, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := options.Sender.SendCode(ctx, target, code); err != nil {
+		s.logger.Error("Failed to send verification SMS",
+			zap.String("phone", utils.MaskPhone(target)),
+			zap.String("type", codeType),
+			zap.Error(err))
+		if markErr := s.MarkCodeAsUsed(ctx, verificationCode.ID); markErr != nil {
+			s.logger.Warn("Failed to invalidate unsent verification code", zap.Uint("code_id", verificationCode.ID), zap.Error(markErr))
+		}
+		if stdErrors.Is(err, sms.ErrRejected) {
+			return nil, errors.NewValidationError("phone", "该手机号暂时无法接收短信")
+		}
+		return nil, errors.NewInternalError("短信发送失败，请稍后再试")
+	}
+	s.cacheCode(ctx, verificationCode)
+
+	s.logger.Info("Verification SMS sent",
+		zap.String("phone", utils.MaskPhone(target)),
+		zap.String("type", codeType),
+		zap.String("ip", ipAddress),
+		zap.Uint("code_id", verificationCode.ID))
+
+	return verificationCode, nil
+}
+
+// VerifyPhoneCode 验证手机验证码，手机号按生成时相同的规则规范化
+func (s *verificationService) VerifyPhoneCode(ctx context.Context, phone, codeType, code string) (_ *models.VerificationCode, err error) {
+	ctx, span := tracing.Start(ctx, "VerificationService.VerifyPhoneCode", attribute.String("verification.type", codeType))
+	defer tracing.Finish(span, &err)
+
+	target, err := s.validatePhoneParams(phone, codeType, s.phoneOptions())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.codeManager.ValidateCodeFormat(code); err != nil {
+		return nil, errors.NewValidationError("code", err.Error())
+	}
+	return s.verifyCode(ctx, target, codeType, code)
+}
+
+// validatePhoneParams 验证验证码类型并返回规范化的手机号
+func (s *verificationService) validatePhoneParams(phone, codeType string, options PhoneOptions) (string, error) {
+	target, err := sms.NormalizePhone(phone, options.DefaultCountryCode)
+	if err != nil {
+		return "", errors.NewValidationError("phone", "手机号格式不正确")
+	}
+	if err := s.codeManager.ValidateCodeType(codeType); err != nil {
+		return "", errors.NewValidationError("code_type", err.Error())
+	}
+	return target, nil
+}
+
+// checkPhoneRateLimit 检查同一手机号每小时的发送条数，未超过时计入本次发送
+//
+// 计数使用 cache.Keys.VerifyAttempt 键，首次计数时设置窗口有效期；
+// 缓存不支持计数或不可用时统计数据库中该号码窗口内的验证码记录，此时本次发送在保存记录后计入
+func (s *verificationService) checkPhoneRateLimit(ctx context.Context, phone string, options PhoneOptions) error {
+	limit := options.HourlyLimit
+	if limit <= 0 {
+		limit = DefaultPhoneHourlyLimit
+	}
+	exceeded := errors.NewValidationError("rate_limit", fmt.Sprintf("该手机号获取验证码过于频繁，每小时最多%d条", limit))
+
+	if counter, ok := s.cacheFor(ctx).(phoneCounter); ok {
+		key := cache.Keys.VerifyAttempt(phoneAttemptType, phone)
+		count, err := counter.Increment(key)
+		if err == nil {
+			if count == 1 {
+				if err := counter.Expire(key, phoneSendWindow); err != nil {
+					s.logger.Warn("Failed to set SMS rate limit window", zap.Error(err))
+				}
+			}
+			if count > int64(limit) {
+				return exceeded
+			}
+			return nil
+		}
+		s.logger.Debug("SMS rate limit counter unavailable, falling back to database", zap.Error(err))
+	}
+
+	var count int64
+	err := database.Conn(ctx, s.db).Model(&models.VerificationCode{}).
+		Where("target = ? AND created_at > ?", phone, s.clock.Now().Add(-phoneSendWindow)).
+		Count(&count).Error
+	if err != nil {
+		s.logger.Error("Failed to check SMS rate limit", zap.Error(err))
+		return errors.NewInternalError("频率检查失败")
+	}
+	if count >= int64(limit) {
+		return exceeded
+	}
+	return nil
+}
+
+// phoneOptions 返回手机验证码选项，未单独设置时使用全局选项
+func (s *verificationService) phoneOptions() PhoneOptions {
+	if s.phone != nil {
+		return *s.phone
+	}
+	return DefaultPhoneOptions()
+}
+
+var (
+	defaultPhoneMu      sync.RWMutex
+	defaultPhoneOptions PhoneOptions
+)
+
+// SetDefaultPhoneOptions 设置全局手机验证码选项，启动时由main根据短信服务配置调用
+func SetDefaultPhoneOptions(options PhoneOptions) {
+	defaultPhoneMu.Lock()
+	defer defaultPhoneMu.Unlock()
+	defaultPhoneOptions = options
+}
+
+// DefaultPhoneOptions 返回全局手机验证码选项，未设置时不支持手机验证码
+func DefaultPhoneOptions() PhoneOptions {
+	defaultPhoneMu.RLock()
+	defer defaultPhoneMu.RUnlock()
+	return defaultPhoneOptions
+}
@@ -0,0 +1,145 @@
+package verification
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"cloudpan/internal/pkg/cache"
+	"cloudpan/internal/pkg/clock"
+	"cloudpan/internal/pkg/sms"
+	"cloudpan/internal/repository/models"
+)
+
+// capturingSMSSender 记录最近发送的号码和验证码，err 非空时模拟发送失败
+type capturingSMSSender struct {
+	phone string
+	code  string
+	sent  int
+	err   error
+}
+
+func (s *capturingSMSSender) SendCode(_ context.Context, phone, code string) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.phone, s.code = phone, code
+	s.sent++
+	return nil
+}
+
+// countingCodeCache 支持计数的内存缓存，记录计数键的有效期
+type countingCodeCache struct {
+	*fakeCodeCache
+	counters map[string]int64
+	ttls     map[string]time.Duration
+}
+
+func (c *countingCodeCache) Increment(key string) (int64, error) {
+	if c.down {
+		return 0, errCacheDown
+	}
+	c.counters[key]++
+	return c.counters[key], nil
+}
+
+func (c *countingCodeCache) Expire(key string, ttl time.Duration) error {
+	c.ttls[key] = ttl
+	return nil
+}
+
+func setupPhoneService(t *testing.T, codeCache CodeCache, clk clock.Clock, options PhoneOptions) (VerificationService, *capturingSMSSender) {
+	service, _, _ := setupVerificationService(t, codeCache, clk)
+	sender := &capturingSMSSender{}
+	if options.Sender == nil {
+		options.Sender = sender
+	}
+	service.(*verificationService).phone = &options
+	return service, sender
+}
+
+func TestGeneratePhoneCode(t *testing.T) {
+	ctx := context.Background()
+	service, sender := setupPhoneService(t, newFakeCodeCache(), nil, PhoneOptions{DefaultCountryCode: "86"})
+
+	userID := uint(7)
+	record, err := service.GeneratePhoneCode(ctx, "138 0013 8000", models.VerificationTypeLogin, &userID, "192.0.2.1")
+	require.NoError(t, err)
+	assert.Equal(t, "+8613800138000", record.Target, "以E.164格式保存")
+	assert.Equal(t, "+8613800138000", sender.phone)
+	assert.NotEqual(t, sender.code, record.CodeHash)
+
+	// 同一号码的其他写法可以验证
+	_, err = service.VerifyPhoneCode(ctx, "+86 13800138000", models.VerificationTypeLogin, wrongCode(sender.code))
+	assert.Error(t, err)
+	verified, err := service.VerifyPhoneCode(ctx, "+86 13800138000", models.VerificationTypeLogin, sender.code)
+	require.NoError(t, err)
+	assert.Equal(t, record.ID, verified.ID)
+
+	// 验证码按用途区分
+	_, err = service.VerifyPhoneCode(ctx, "13800138000", models.VerificationTypeMFA, sender.code)
+	assert.Error(t, err)
+
+	_, err = service.GeneratePhoneCode(ctx, "12345", models.VerificationTypeLogin, nil, "192.0.2.1")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "手机号格式不正确")
+}
+
+func TestGeneratePhoneCode_Disabled(t *testing.T) {
+	service, _, _ := setupVerificationService(t, nil, nil)
+	_, err := service.GeneratePhoneCode(context.Background(), "+8613800138000", models.VerificationTypeLogin, nil, "192.0.2.1")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "未启用")
+}
+
+func TestGeneratePhoneCode_SendFailure(t *testing.T) {
+	ctx := context.Background()
+	sender := &capturingSMSSender{err: fmt.Errorf("%w: isv.MOBILE_NUMBER_ILLEGAL", sms.ErrRejected)}
+	service, _ := setupPhoneService(t, newFakeCodeCache(), nil, PhoneOptions{Sender: sender})
+
+	_, err := service.GeneratePhoneCode(ctx, "+8613800138000", models.VerificationTypeLogin, nil, "192.0.2.1")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "无法接收短信")
+
+	// 未送达的验证码已作废
+	active, err := service.GetActiveCode(ctx, "+8613800138000", models.VerificationTypeLogin)
+	require.NoError(t, err)
+	assert.Nil(t, active)
+
+	sender.err = fmt.Errorf("sms: aliyun request failed: timeout")
+	_, err = service.GeneratePhoneCode(ctx, "+8613800138000", models.VerificationTypeLogin, nil, "192.0.2.2")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "短信发送失败")
+}
+
+func TestGeneratePhoneCode_RateLimit(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+	codeCache := &countingCodeCache{fakeCodeCache: newFakeCodeCache(), counters: map[string]int64{}, ttls: map[string]time.Duration{}}
+	service, sender := setupPhoneService(t, codeCache, clk, PhoneOptions{HourlyLimit: 2})
+	key := cache.Keys.VerifyAttempt(phoneAttemptType, "+8613800138000")
+
+	// 不同用途共用每小时的发送条数
+	_, err := service.GeneratePhoneCode(ctx, "+8613800138000", models.VerificationTypeLogin, nil, "192.0.2.1")
+	require.NoError(t, err)
+	_, err = service.GeneratePhoneCode(ctx, "+8613800138000", models.VerificationTypeMFA, nil, "192.0.2.2")
+	require.NoError(t, err)
+	_, err = service.GeneratePhoneCode(ctx, "+8613800138000", models.VerificationTypeLogin, nil, "192.0.2.3")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "每小时最多2条")
+	assert.Equal(t, 2, sender.sent)
+	assert.Equal(t, time.Hour, codeCache.ttls[key])
+
+	// 缓存不可用时按数据库记录统计
+	codeCache.down = true
+	_, err = service.GeneratePhoneCode(ctx, "+8613800138000", models.VerificationTypeLogin, nil, "192.0.2.4")
+	require.Error(t, err)
+	clk.Advance(time.Hour + time.Second)
+	_, err = service.GeneratePhoneCode(ctx, "+8613800138000", models.VerificationTypeLogin, nil, "192.0.2.5")
+	require.NoError(t, err)
+	assert.Equal(t, 3, sender.sent)
+}
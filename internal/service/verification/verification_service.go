@@ -15,11 +15,15 @@ import (
 // 3. 验证码管理：过期清理、使用状态管理
 // 4. 安全防护：频率限制、尝试次数限制
 //
+// 验证码以加盐哈希保存在数据库中，数据库是唯一可信来源；设置缓存后当前验证码同时写入Redis，
+// 验证时免去查询，缓存不可用或与数据库不一致时回退到数据库，Redis故障期间生成和验证都不受影响
+//
 // 使用示例：
 //
-//	service := NewVerificationService(db, emailService, logger)
+//	service := NewVerificationService(db, emailService, cache.NewCacheManager(), clock.Real(), logger)
 //	code, err := service.GenerateEmailCode(ctx, email, "password_reset", userID, request.RemoteAddr)
 //	isValid, err := service.VerifyEmailCode(ctx, email, "password_reset", inputCode)
+//	step, err := service.ValidateTOTPCode(secret, inputCode, lastUsedStep)
 type VerificationService interface {
 	// 验证码生成
 	GenerateEmailCode(ctx context.Context, email, codeType string, userID *uint, ipAddress string) (*models.VerificationCode, error)
@@ -32,7 +36,7 @@ type VerificationService interface {
 	// 验证码管理
 	GetActiveCode(ctx context.Context, target, codeType string) (*models.VerificationCode, error)
 	InvalidateCode(ctx context.Context, codeID uint) error
-	CleanupExpiredCodes(ctx context.Context) error
+	CleanupExpiredCodes(ctx context.Context) (int64, error)
 
 	// 安全检查
 	CheckRateLimit(ctx context.Context, target, codeType string, ipAddress string) error
@@ -54,6 +58,18 @@ type VerificationService interface {
 	// 批量操作
 	CleanupUserCodes(ctx context.Context, userID uint, codeType string) error
 	GetUserActiveCodes(ctx context.Context, userID uint) ([]*models.VerificationCode, error)
+
+	// TOTP两步验证
+	GenerateTOTPSecret() (string, error)
+	TOTPProvisioningURI(issuer, account, secret string) string
+	ValidateTOTPCode(secret, code string, lastUsedStep int64) (int64, error)
+}
+
+// CodeCache 当前验证码缓存，由 cache.CacheManager 实现
+type CodeCache interface {
+	Get(key string, dest interface{}) error
+	SetWithTTL(key string, value interface{}, ttl time.Duration) error
+	Delete(keys ...string) error
 }
 
 // CodeGenerationRequest 验证码生成请求
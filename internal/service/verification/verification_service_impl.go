@@ -4,11 +4,16 @@ import (
 	"context"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 
+	"cloudpan/internal/pkg/cache"
+	"cloudpan/internal/pkg/clock"
+	"cloudpan/internal/pkg/database"
 	"cloudpan/internal/pkg/email"
 	"cloudpan/internal/pkg/errors"
+	"cloudpan/internal/pkg/tracing"
 	"cloudpan/internal/pkg/utils"
 	"cloudpan/internal/repository/models"
 )
@@ -17,24 +22,52 @@ import (
 type verificationService struct {
 	db           *gorm.DB
 	emailService email.EmailService
+	codeCache    CodeCache
 	logger       *zap.Logger
 	codeManager  utils.EmailCodeManager
 	validator    utils.Validator
+	clock        clock.Clock
+	phone        *PhoneOptions // 手机验证码选项，为nil时使用全局选项
 }
 
-// NewVerificationService 创建验证码服务实例
-func NewVerificationService(db *gorm.DB, emailService email.EmailService, logger *zap.Logger) VerificationService {
+// contextCodeCache 可绑定请求上下文的验证码缓存，缓存操作挂在请求的追踪链路下
+type contextCodeCache interface {
+	WithContext(ctx context.Context) *cache.CacheManager
+}
+
+// totpSkew TOTP验证允许的时钟偏差(时间步数)
+const totpSkew = 1
+
+// cachedCode 缓存中的当前验证码，与数据库记录一样只保存哈希和盐值
+type cachedCode struct {
+	ID        uint      `json:"id"`
+	CodeHash  string    `json:"code_hash"`
+	Salt      string    `json:"salt"`
+	ExpiresAt time.Time `json:"expires_at"`
+	UserID    *uint     `json:"user_id,omitempty"`
+}
+
+// NewVerificationService 创建验证码服务实例，codeCache为nil时每次验证都查询数据库，clk为nil时使用系统时钟
+func NewVerificationService(db *gorm.DB, emailService email.EmailService, codeCache CodeCache, clk clock.Clock, logger *zap.Logger) VerificationService {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
 	return &verificationService{
 		db:           db,
 		emailService: emailService,
+		codeCache:    codeCache,
 		logger:       logger,
 		codeManager:  utils.NewEmailCodeManager(),
 		validator:    utils.NewValidator(),
+		clock:        clock.OrReal(clk),
 	}
 }
 
 // GenerateEmailCode 生成邮箱验证码
-func (s *verificationService) GenerateEmailCode(ctx context.Context, email, codeType string, userID *uint, ipAddress string) (*models.VerificationCode, error) {
+func (s *verificationService) GenerateEmailCode(ctx context.Context, email, codeType string, userID *uint, ipAddress string) (_ *models.VerificationCode, err error) {
+	ctx, span := tracing.Start(ctx, "VerificationService.GenerateEmailCode", attribute.String("verification.type", codeType))
+	defer tracing.Finish(span, &err)
+
 	// 验证输入参数
 	if err := s.validateCodeGenerationParams(email, codeType); err != nil {
 		return nil, err
@@ -61,9 +94,10 @@ func (s *verificationService) GenerateEmailCode(ctx context.Context, email, code
 	if err != nil {
 		return nil, err
 	}
+	s.cacheCode(ctx, verificationCode)
 
-	// 发送邮件
-	if err := s.sendVerificationEmail(ctx, email, code, codeType); err != nil {
+	// 发送邮件，已知用户且调用方未指定语言时使用用户的语言偏好
+	if err := s.sendVerificationEmail(s.withRecipientLanguage(ctx, userID), email, code, codeType); err != nil {
 		s.logger.Error("Failed to send verification email",
 			zap.String("email", email),
 			zap.String("type", codeType),
@@ -130,8 +164,10 @@ func (s *verificationService) createAndSaveCode(ctx context.Context, email, code
 		IPAddress: ipAddress,
 		UserID:    userID,
 	}
+	// 创建时间同样取自时钟，频率限制按创建时间统计
+	verificationCode.CreatedAt = s.clock.Now()
 
-	if err := s.db.WithContext(ctx).Create(verificationCode).Error; err != nil {
+	if err := database.Conn(ctx, s.db).Create(verificationCode).Error; err != nil {
 		s.logger.Error("Failed to save verification code", zap.Error(err))
 		return nil, errors.NewInternalError("验证码保存失败")
 	}
@@ -142,11 +178,11 @@ func (s *verificationService) createAndSaveCode(ctx context.Context, email, code
 func (s *verificationService) calculateExpirationTime(codeType string) time.Time {
 	switch codeType {
 	case models.VerificationTypeResetPassword:
-		return time.Now().Add(30 * time.Minute) // 密码重置30分钟
-	case models.VerificationTypeLogin:
-		return time.Now().Add(5 * time.Minute) // 登录验证5分钟
+		return s.clock.Now().Add(30 * time.Minute) // 密码重置30分钟
+	case models.VerificationTypeLogin, models.VerificationTypeMFA:
+		return s.clock.Now().Add(5 * time.Minute) // 登录和两步验证5分钟
 	default:
-		return time.Now().Add(15 * time.Minute) // 默认15分钟
+		return s.clock.Now().Add(15 * time.Minute) // 默认15分钟
 	}
 }
 
@@ -156,7 +192,10 @@ func (s *verificationService) GeneratePasswordResetCode(ctx context.Context, ema
 }
 
 // VerifyEmailCode 验证邮箱验证码
-func (s *verificationService) VerifyEmailCode(ctx context.Context, email, codeType, code string) (*models.VerificationCode, error) {
+func (s *verificationService) VerifyEmailCode(ctx context.Context, email, codeType, code string) (_ *models.VerificationCode, err error) {
+	ctx, span := tracing.Start(ctx, "VerificationService.VerifyEmailCode", attribute.String("verification.type", codeType))
+	defer tracing.Finish(span, &err)
+
 	// 验证输入参数
 	if err := s.validator.ValidateEmail(email); err != nil {
 		return nil, errors.NewValidationError("email", err.Error())
@@ -169,12 +208,55 @@ func (s *verificationService) VerifyEmailCode(ctx context.Context, email, codeTy
 	if err := s.codeManager.ValidateCodeType(codeType); err != nil {
 		return nil, errors.NewValidationError("code_type", err.Error())
 	}
+	return s.verifyCode(ctx, email, codeType, code)
+}
 
-	// 查找有效的验证码
+// verifyCode 核对目标当前有效的验证码，每次核对计入一次尝试
+func (s *verificationService) verifyCode(ctx context.Context, target, codeType, code string) (*models.VerificationCode, error) {
+	// 优先使用缓存中的当前验证码，尝试次数始终在数据库中记录
+	verificationCode := s.cachedActiveCode(ctx, target, codeType)
+	if verificationCode != nil {
+		consumed, err := s.consumeAttempt(ctx, verificationCode.ID)
+		if err != nil {
+			return nil, err
+		}
+		if !consumed {
+			// 缓存的验证码已使用、已被新验证码替换或尝试次数用尽，以数据库为准
+			s.forgetCode(ctx, target, codeType)
+			verificationCode = nil
+		}
+	}
+	if verificationCode == nil {
+		var err error
+		if verificationCode, err = s.findActiveCode(ctx, target, codeType); err != nil {
+			return nil, err
+		}
+	}
+
+	// 验证验证码
+	isValid := s.codeManager.HashVerificationCode(code, verificationCode.Salt) == verificationCode.CodeHash
+	if !isValid {
+		s.logger.Warn("Invalid verification code attempt",
+			zap.String("target", target),
+			zap.String("type", codeType),
+			zap.Uint("code_id", verificationCode.ID))
+		return nil, errors.NewValidationError("code", "验证码错误")
+	}
+
+	s.logger.Info("Verification code verified successfully",
+		zap.String("target", target),
+		zap.String("type", codeType),
+		zap.Uint("code_id", verificationCode.ID))
+
+	return verificationCode, nil
+}
+
+// findActiveCode 从数据库查找当前有效的验证码并计入一次尝试，查到后重新写入缓存
+func (s *verificationService) findActiveCode(ctx context.Context, target, codeType string) (*models.VerificationCode, error) {
 	var verificationCode models.VerificationCode
-	err := s.db.WithContext(ctx).Where(
+	err := database.Conn(ctx, s.db).Where(
 		"target = ? AND type = ? AND is_used = false AND expires_at > ?",
-		email, codeType, time.Now(),
+		target, codeType, s.clock.Now(),
 	).Order("created_at DESC").First(&verificationCode).Error
 
 	if err != nil {
@@ -190,26 +272,103 @@ func (s *verificationService) VerifyEmailCode(ctx context.Context, email, codeTy
 		return nil, errors.NewValidationError("code", "验证码尝试次数过多，请重新获取")
 	}
 
-	// 增加尝试次数
+	consumed, err := s.consumeAttempt(ctx, verificationCode.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !consumed {
+		// 查询后被并发请求用完尝试次数或使用
+		return nil, errors.NewValidationError("code", "验证码不存在或已过期")
+	}
 	verificationCode.AttemptCount++
-	s.db.WithContext(ctx).Model(&verificationCode).Update("attempt_count", verificationCode.AttemptCount)
+	s.cacheCode(ctx, &verificationCode)
+	return &verificationCode, nil
+}
 
-	// 验证验证码
-	isValid := s.codeManager.HashVerificationCode(code, verificationCode.Salt) == verificationCode.CodeHash
-	if !isValid {
-		s.logger.Warn("Invalid verification code attempt",
-			zap.String("target", email),
-			zap.String("type", codeType),
-			zap.Int("attempt", verificationCode.AttemptCount))
-		return nil, errors.NewValidationError("code", "验证码错误")
+// consumeAttempt 原子地为有效验证码增加一次尝试次数，验证码已使用、已过期或尝试次数用尽时返回false
+func (s *verificationService) consumeAttempt(ctx context.Context, codeID uint) (bool, error) {
+	result := database.Conn(ctx, s.db).Model(&models.VerificationCode{}).
+		Where("id = ? AND is_used = false AND expires_at > ? AND attempt_count < max_attempts", codeID, s.clock.Now()).
+		Update("attempt_count", gorm.Expr("attempt_count + 1"))
+	if result.Error != nil {
+		s.logger.Error("Failed to record verification attempt",
+			zap.Uint("code_id", codeID),
+			zap.Error(result.Error))
+		return false, errors.NewInternalError("验证码查询失败")
 	}
+	return result.RowsAffected > 0, nil
+}
 
-	s.logger.Info("Verification code verified successfully",
-		zap.String("target", email),
-		zap.String("type", codeType),
-		zap.Uint("code_id", verificationCode.ID))
+// cachedActiveCode 读取缓存中的当前验证码，未设置缓存、缓存不可用或未命中时返回nil
+func (s *verificationService) cachedActiveCode(ctx context.Context, target, codeType string) *models.VerificationCode {
+	if s.codeCache == nil {
+		return nil
+	}
+	var cached cachedCode
+	if err := s.cacheFor(ctx).Get(cache.Keys.VerifyCode(codeType, target), &cached); err != nil {
+		return nil
+	}
+	if cached.ID == 0 || !cached.ExpiresAt.After(s.clock.Now()) {
+		return nil
+	}
 
-	return &verificationCode, nil
+	verificationCode := &models.VerificationCode{
+		Target:    target,
+		Type:      codeType,
+		CodeHash:  cached.CodeHash,
+		Salt:      cached.Salt,
+		ExpiresAt: cached.ExpiresAt,
+		UserID:    cached.UserID,
+	}
+	verificationCode.ID = cached.ID
+	return verificationCode
+}
+
+// cacheCode 将当前验证码写入缓存，有效期与验证码一致
+//
+// 缓存只是加速，写入失败不影响验证码生成；写入失败时尽量删除旧缓存，
+// 残留的旧验证码在验证时因数据库中已失效而被忽略
+func (s *verificationService) cacheCode(ctx context.Context, verificationCode *models.VerificationCode) {
+	if s.codeCache == nil {
+		return
+	}
+	ttl := time.Until(verificationCode.ExpiresAt)
+	if ttl <= 0 {
+		return
+	}
+
+	key := cache.Keys.VerifyCode(verificationCode.Type, verificationCode.Target)
+	err := s.cacheFor(ctx).SetWithTTL(key, cachedCode{
+		ID:        verificationCode.ID,
+		CodeHash:  verificationCode.CodeHash,
+		Salt:      verificationCode.Salt,
+		ExpiresAt: verificationCode.ExpiresAt,
+		UserID:    verificationCode.UserID,
+	}, ttl)
+	if err != nil {
+		s.logger.Warn("Failed to cache verification code, falling back to database",
+			zap.Uint("code_id", verificationCode.ID),
+			zap.Error(err))
+		s.forgetCode(ctx, verificationCode.Target, verificationCode.Type)
+	}
+}
+
+// cacheFor 返回绑定ctx的验证码缓存，缓存实现不支持绑定上下文时原样返回
+func (s *verificationService) cacheFor(ctx context.Context) CodeCache {
+	if bound, ok := s.codeCache.(contextCodeCache); ok {
+		return bound.WithContext(ctx)
+	}
+	return s.codeCache
+}
+
+// forgetCode 删除缓存中的当前验证码，失败时仅记录日志
+func (s *verificationService) forgetCode(ctx context.Context, target, codeType string) {
+	if s.codeCache == nil {
+		return
+	}
+	if err := s.cacheFor(ctx).Delete(cache.Keys.VerifyCode(codeType, target)); err != nil {
+		s.logger.Debug("Failed to delete cached verification code", zap.String("type", codeType), zap.Error(err))
+	}
 }
 
 // VerifyPasswordResetCode 验证密码重置验证码
@@ -223,9 +382,12 @@ func (s *verificationService) CompletePasswordReset(ctx context.Context, codeID
 }
 
 // MarkCodeAsUsed 标记验证码为已使用
-func (s *verificationService) MarkCodeAsUsed(ctx context.Context, codeID uint) error {
-	now := time.Now()
-	result := s.db.WithContext(ctx).Model(&models.VerificationCode{}).
+func (s *verificationService) MarkCodeAsUsed(ctx context.Context, codeID uint) (err error) {
+	ctx, span := tracing.Start(ctx, "VerificationService.MarkCodeAsUsed", attribute.Int64("verification.code_id", int64(codeID)))
+	defer tracing.Finish(span, &err)
+
+	now := s.clock.Now()
+	result := database.Conn(ctx, s.db).Model(&models.VerificationCode{}).
 		Where("id = ? AND is_used = false", codeID).
 		Updates(map[string]interface{}{
 			"is_used": true,
@@ -247,12 +409,15 @@ func (s *verificationService) MarkCodeAsUsed(ctx context.Context, codeID uint) e
 }
 
 // CheckRateLimit 检查频率限制
-func (s *verificationService) CheckRateLimit(ctx context.Context, target, codeType string, ipAddress string) error {
+func (s *verificationService) CheckRateLimit(ctx context.Context, target, codeType string, ipAddress string) (err error) {
+	ctx, span := tracing.Start(ctx, "VerificationService.CheckRateLimit", attribute.String("verification.type", codeType))
+	defer tracing.Finish(span, &err)
+
 	// 检查同一邮箱的频率限制（5分钟内最多3次）
 	count := int64(0)
-	fiveMinutesAgo := time.Now().Add(-5 * time.Minute)
+	fiveMinutesAgo := s.clock.Now().Add(-5 * time.Minute)
 
-	err := s.db.WithContext(ctx).Model(&models.VerificationCode{}).
+	err = database.Conn(ctx, s.db).Model(&models.VerificationCode{}).
 		Where("target = ? AND type = ? AND created_at > ?", target, codeType, fiveMinutesAgo).
 		Count(&count).Error
 
@@ -266,8 +431,8 @@ func (s *verificationService) CheckRateLimit(ctx context.Context, target, codeTy
 	}
 
 	// 检查同一IP的频率限制（1小时内最多10次）
-	oneHourAgo := time.Now().Add(-1 * time.Hour)
-	err = s.db.WithContext(ctx).Model(&models.VerificationCode{}).
+	oneHourAgo := s.clock.Now().Add(-1 * time.Hour)
+	err = database.Conn(ctx, s.db).Model(&models.VerificationCode{}).
 		Where("ip_address = ? AND created_at > ?", ipAddress, oneHourAgo).
 		Count(&count).Error
 
@@ -286,9 +451,9 @@ func (s *verificationService) CheckRateLimit(ctx context.Context, target, codeTy
 // GetActiveCode 获取活跃的验证码
 func (s *verificationService) GetActiveCode(ctx context.Context, target, codeType string) (*models.VerificationCode, error) {
 	var verificationCode models.VerificationCode
-	err := s.db.WithContext(ctx).Where(
+	err := database.Conn(ctx, s.db).Where(
 		"target = ? AND type = ? AND is_used = false AND expires_at > ?",
-		target, codeType, time.Now(),
+		target, codeType, s.clock.Now(),
 	).Order("created_at DESC").First(&verificationCode).Error
 
 	if err != nil {
@@ -301,16 +466,44 @@ func (s *verificationService) GetActiveCode(ctx context.Context, target, codeTyp
 	return &verificationCode, nil
 }
 
-// CleanupExpiredCodes 清理过期验证码
-func (s *verificationService) CleanupExpiredCodes(ctx context.Context) error {
-	result := s.db.WithContext(ctx).Where("expires_at < ?", time.Now()).Delete(&models.VerificationCode{})
-	if result.Error != nil {
-		s.logger.Error("Failed to cleanup expired codes", zap.Error(result.Error))
-		return result.Error
+// cleanupBatchSize 清理过期验证码时每批删除的记录数
+const cleanupBatchSize = 500
+
+// CleanupExpiredCodes 分批物理删除过期验证码，返回删除的记录数
+//
+// 过期验证码不再有任何用途，连同已软删除的记录一起清除；每批单独执行，避免长事务锁表
+func (s *verificationService) CleanupExpiredCodes(ctx context.Context) (int64, error) {
+	now := s.clock.Now()
+	var total int64
+	for {
+		var ids []uint
+		err := database.Conn(ctx, s.db).Unscoped().Model(&models.VerificationCode{}).
+			Where("expires_at < ?", now).
+			Limit(cleanupBatchSize).
+			Pluck("id", &ids).Error
+		if err != nil {
+			s.logger.Error("Failed to cleanup expired codes", zap.Error(err))
+			return total, err
+		}
+		if len(ids) == 0 {
+			break
+		}
+
+		result := database.Conn(ctx, s.db).Unscoped().Where("id IN ?", ids).Delete(&models.VerificationCode{})
+		if result.Error != nil {
+			s.logger.Error("Failed to cleanup expired codes", zap.Error(result.Error))
+			return total, result.Error
+		}
+		total += result.RowsAffected
+		if len(ids) < cleanupBatchSize {
+			break
+		}
 	}
 
-	s.logger.Info("Cleaned up expired verification codes", zap.Int64("count", result.RowsAffected))
-	return nil
+	if total > 0 {
+		s.logger.Info("Cleaned up expired verification codes", zap.Int64("count", total))
+	}
+	return total, nil
 }
 
 // sendVerificationEmail 发送验证邮件
@@ -325,31 +518,41 @@ func (s *verificationService) sendVerificationEmail(ctx context.Context, email,
 	}
 }
 
+// withRecipientLanguage 调用方未通过 email.WithLanguage 指定语言时，读取用户保存的界面语言偏好
+//
+// 用户为空、没有设置偏好或查询失败时返回原ctx，邮件使用默认语言
+func (s *verificationService) withRecipientLanguage(ctx context.Context, userID *uint) context.Context {
+	if userID == nil || email.LanguageFromContext(ctx) != "" {
+		return ctx
+	}
+
+	var preference models.UserPreference
+	err := database.Conn(ctx, s.db).
+		Where("user_id = ? AND category = ? AND `key` = ?", *userID, models.PreferenceCategoryUI, models.PreferenceKeyLanguage).
+		First(&preference).Error
+	if err != nil || preference.GetStringValue() == "" {
+		return ctx
+	}
+	return email.WithLanguage(ctx, preference.GetStringValue())
+}
+
 // invalidateOldCodes 使旧验证码失效
 func (s *verificationService) invalidateOldCodes(ctx context.Context, target, codeType string) error {
-	return s.db.WithContext(ctx).Model(&models.VerificationCode{}).
+	return database.Conn(ctx, s.db).Model(&models.VerificationCode{}).
 		Where("target = ? AND type = ? AND is_used = false", target, codeType).
 		Update("is_used", true).Error
 }
 
 // 实现其他接口方法的简化版本
 
-func (s *verificationService) GeneratePhoneCode(ctx context.Context, phone, codeType string, userID *uint, ipAddress string) (*models.VerificationCode, error) {
-	return nil, errors.NewValidationError("phone", "手机验证码功能尚未实现")
-}
-
-func (s *verificationService) VerifyPhoneCode(ctx context.Context, phone, codeType, code string) (*models.VerificationCode, error) {
-	return nil, errors.NewValidationError("phone", "手机验证码功能尚未实现")
-}
-
 func (s *verificationService) InvalidateCode(ctx context.Context, codeID uint) error {
 	return s.MarkCodeAsUsed(ctx, codeID)
 }
 
 func (s *verificationService) GetAttemptCount(ctx context.Context, target, codeType string, timeWindow time.Duration) (int, error) {
 	var count int64
-	since := time.Now().Add(-timeWindow)
-	err := s.db.WithContext(ctx).Model(&models.VerificationCode{}).
+	since := s.clock.Now().Add(-timeWindow)
+	err := database.Conn(ctx, s.db).Model(&models.VerificationCode{}).
 		Where("target = ? AND type = ? AND created_at > ?", target, codeType, since).
 		Count(&count).Error
 	return int(count), err
@@ -357,7 +560,7 @@ func (s *verificationService) GetAttemptCount(ctx context.Context, target, codeT
 
 func (s *verificationService) IsCodeValid(ctx context.Context, codeID uint) (bool, error) {
 	var verificationCode models.VerificationCode
-	err := s.db.WithContext(ctx).First(&verificationCode, codeID).Error
+	err := database.Conn(ctx, s.db).First(&verificationCode, codeID).Error
 	if err != nil {
 		return false, err
 	}
@@ -373,14 +576,36 @@ func (s *verificationService) VerifyEmailVerificationCode(ctx context.Context, e
 }
 
 func (s *verificationService) CleanupUserCodes(ctx context.Context, userID uint, codeType string) error {
-	return s.db.WithContext(ctx).Where("user_id = ? AND type = ?", userID, codeType).Delete(&models.VerificationCode{}).Error
+	return database.Conn(ctx, s.db).Where("user_id = ? AND type = ?", userID, codeType).Delete(&models.VerificationCode{}).Error
 }
 
 func (s *verificationService) GetUserActiveCodes(ctx context.Context, userID uint) ([]*models.VerificationCode, error) {
 	var codes []*models.VerificationCode
-	err := s.db.WithContext(ctx).Where(
+	err := database.Conn(ctx, s.db).Where(
 		"user_id = ? AND is_used = false AND expires_at > ?",
-		userID, time.Now(),
+		userID, s.clock.Now(),
 	).Find(&codes).Error
 	return codes, err
 }
+
+// GenerateTOTPSecret 生成新的TOTP密钥
+func (s *verificationService) GenerateTOTPSecret() (string, error) {
+	return utils.GenerateTOTPSecret()
+}
+
+// TOTPProvisioningURI 生成验证器应用扫码登记使用的URI
+func (s *verificationService) TOTPProvisioningURI(issuer, account, secret string) string {
+	return utils.TOTPProvisioningURI(issuer, account, secret)
+}
+
+// ValidateTOTPCode 验证TOTP验证码，返回匹配的时间步
+//
+// 允许前后一个时间步的时钟偏差；匹配的时间步不晚于lastUsedStep时视为重放，
+// 同一验证码在有效期内只能使用一次
+func (s *verificationService) ValidateTOTPCode(secret, code string, lastUsedStep int64) (int64, error) {
+	step, ok := utils.ValidateTOTP(secret, code, s.clock.Now(), totpSkew)
+	if !ok || step <= lastUsedStep {
+		return 0, errors.WrapError(errors.ErrInvalidInput, "验证码错误或已使用")
+	}
+	return step, nil
+}
@@ -0,0 +1,230 @@
+package verification
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	_ "modernc.org/sqlite" // 使用纯Go的SQLite驱动
+
+	"cloudpan/internal/pkg/cache"
+	"cloudpan/internal/pkg/clock"
+	"cloudpan/internal/pkg/email"
+	"cloudpan/internal/repository/models"
+)
+
+var errCacheDown = errors.New("redis: connection refused")
+
+// fakeCodeCache 内存缓存，down为true时模拟Redis故障
+type fakeCodeCache struct {
+	items map[string][]byte
+	down  bool
+}
+
+func newFakeCodeCache() *fakeCodeCache {
+	return &fakeCodeCache{items: map[string][]byte{}}
+}
+
+func (c *fakeCodeCache) Get(key string, dest interface{}) error {
+	if c.down {
+		return errCacheDown
+	}
+	data, ok := c.items[key]
+	if !ok {
+		return errors.New("cache miss")
+	}
+	return json.Unmarshal(data, dest)
+}
+
+func (c *fakeCodeCache) SetWithTTL(key string, value interface{}, _ time.Duration) error {
+	if c.down {
+		return errCacheDown
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	c.items[key] = data
+	return nil
+}
+
+func (c *fakeCodeCache) Delete(keys ...string) error {
+	if c.down {
+		return errCacheDown
+	}
+	for _, key := range keys {
+		delete(c.items, key)
+	}
+	return nil
+}
+
+// capturingEmailService 记录最近发送的验证码及邮件语言
+type capturingEmailService struct {
+	email.EmailService
+	code     string
+	language string
+}
+
+func (s *capturingEmailService) SendVerificationCode(ctx context.Context, _ string, code string) error {
+	s.code = code
+	s.language = email.LanguageFromContext(ctx)
+	return nil
+}
+
+func setupVerificationService(t *testing.T, codeCache CodeCache, clk clock.Clock) (VerificationService, *capturingEmailService, *gorm.DB) {
+	sqlDB, err := sql.Open("sqlite", ":memory:")
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	db, err := gorm.Open(sqlite.Dialector{Conn: sqlDB}, &gorm.Config{})
+	require.NoError(t, err)
+	// 用户模型含SQLite不支持的enum列，直接建表
+	require.NoError(t, db.Exec(`CREATE TABLE verification_codes (
+		id integer PRIMARY KEY AUTOINCREMENT, created_at datetime, updated_at datetime, deleted_at datetime,
+		version integer DEFAULT 1, uuid text NOT NULL, target text NOT NULL, type text NOT NULL,
+		code text NOT NULL DEFAULT '', code_hash text NOT NULL, salt text NOT NULL,
+		is_used numeric DEFAULT false, used_at datetime, expires_at datetime NOT NULL,
+		attempt_count integer DEFAULT 0, max_attempts integer DEFAULT 5,
+		ip_address text NOT NULL, user_agent text, user_id integer)`).Error)
+
+	mailer := &capturingEmailService{}
+	return NewVerificationService(db, mailer, codeCache, clk, nil), mailer, db
+}
+
+func TestVerifyEmailCode_RedisDown(t *testing.T) {
+	ctx := context.Background()
+	codeCache := newFakeCodeCache()
+	codeCache.down = true
+	service, mailer, db := setupVerificationService(t, codeCache, nil)
+
+	record, err := service.GenerateEmailCode(ctx, "alice@example.com", models.VerificationTypeRegister, nil, "192.0.2.1")
+	require.NoError(t, err)
+
+	var stored models.VerificationCode
+	require.NoError(t, db.First(&stored, record.ID).Error)
+	assert.NotEmpty(t, stored.CodeHash)
+	assert.NotEqual(t, mailer.code, stored.CodeHash) // 只保存哈希
+
+	_, err = service.VerifyEmailCode(ctx, "alice@example.com", models.VerificationTypeRegister, wrongCode(mailer.code))
+	assert.Error(t, err)
+
+	verified, err := service.VerifyEmailCode(ctx, "alice@example.com", models.VerificationTypeRegister, mailer.code)
+	require.NoError(t, err)
+	assert.Equal(t, record.ID, verified.ID)
+	require.NoError(t, db.First(&stored, record.ID).Error)
+	assert.Equal(t, 2, stored.AttemptCount)
+}
+
+func TestGenerateEmailCode_RecipientLanguage(t *testing.T) {
+	ctx := context.Background()
+	service, mailer, db := setupVerificationService(t, newFakeCodeCache(), nil)
+	require.NoError(t, db.Exec(`CREATE TABLE user_preferences (
+		id integer PRIMARY KEY AUTOINCREMENT, created_at datetime, updated_at datetime, deleted_at datetime,
+		version integer DEFAULT 1, user_id integer NOT NULL, category text NOT NULL, key text NOT NULL,
+		value text, value_type text DEFAULT 'string', description text, is_public numeric DEFAULT false)`).Error)
+	require.NoError(t, db.Exec(`INSERT INTO user_preferences (user_id, category, key, value) VALUES (?, ?, ?, ?)`,
+		7, models.PreferenceCategoryUI, models.PreferenceKeyLanguage, "en-US").Error)
+
+	userID := uint(7)
+	_, err := service.GenerateEmailCode(ctx, "carol@example.com", models.VerificationTypeRegister, &userID, "192.0.2.1")
+	require.NoError(t, err)
+	assert.Equal(t, "en-US", mailer.language, "使用用户保存的语言偏好")
+
+	_, err = service.GenerateEmailCode(email.WithLanguage(ctx, "ja"), "carol@example.com", models.VerificationTypeRegister, &userID, "192.0.2.2")
+	require.NoError(t, err)
+	assert.Equal(t, "ja-JP", mailer.language, "调用方指定的语言优先")
+
+	otherID := uint(8)
+	_, err = service.GenerateEmailCode(ctx, "dave@example.com", models.VerificationTypeRegister, &otherID, "192.0.2.3")
+	require.NoError(t, err)
+	assert.Empty(t, mailer.language, "没有偏好时使用默认语言")
+}
+
+func TestVerifyEmailCode_CacheAccelerator(t *testing.T) {
+	ctx := context.Background()
+	codeCache := newFakeCodeCache()
+	service, mailer, _ := setupVerificationService(t, codeCache, nil)
+
+	first, err := service.GenerateEmailCode(ctx, "bob@example.com", models.VerificationTypeRegister, nil, "192.0.2.1")
+	require.NoError(t, err)
+	assert.Len(t, codeCache.items, 1)
+
+	verified, err := service.VerifyEmailCode(ctx, "bob@example.com", models.VerificationTypeRegister, mailer.code)
+	require.NoError(t, err)
+	require.NoError(t, service.MarkCodeAsUsed(ctx, verified.ID))
+
+	// 已使用的验证码仍在缓存中，数据库拒绝后不能再次使用
+	_, err = service.VerifyEmailCode(ctx, "bob@example.com", models.VerificationTypeRegister, mailer.code)
+	assert.Error(t, err)
+
+	// Redis故障期间生成的新验证码未写入缓存，恢复后缓存中残留旧验证码时以数据库为准
+	codeCache.down = true
+	second, err := service.GenerateEmailCode(ctx, "bob@example.com", models.VerificationTypeRegister, nil, "192.0.2.1")
+	require.NoError(t, err)
+	codeCache.down = false
+	require.NoError(t, codeCache.SetWithTTL(cache.Keys.VerifyCode(models.VerificationTypeRegister, "bob@example.com"), cachedCode{
+		ID: first.ID, CodeHash: first.CodeHash, Salt: first.Salt, ExpiresAt: first.ExpiresAt,
+	}, time.Minute))
+
+	verified, err = service.VerifyEmailCode(ctx, "bob@example.com", models.VerificationTypeRegister, mailer.code)
+	require.NoError(t, err)
+	assert.Equal(t, second.ID, verified.ID)
+}
+
+func TestVerifyEmailCode_MaxAttempts(t *testing.T) {
+	ctx := context.Background()
+	service, mailer, _ := setupVerificationService(t, newFakeCodeCache(), nil)
+
+	_, err := service.GenerateEmailCode(ctx, "carol@example.com", models.VerificationTypeRegister, nil, "192.0.2.1")
+	require.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		_, err = service.VerifyEmailCode(ctx, "carol@example.com", models.VerificationTypeRegister, wrongCode(mailer.code))
+		require.Error(t, err)
+	}
+
+	_, err = service.VerifyEmailCode(ctx, "carol@example.com", models.VerificationTypeRegister, mailer.code)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "尝试次数过多")
+}
+
+func TestVerifyEmailCode_Expiry(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+	codeCache := newFakeCodeCache()
+	service, mailer, _ := setupVerificationService(t, codeCache, clk)
+
+	record, err := service.GenerateEmailCode(ctx, "dave@example.com", models.VerificationTypeRegister, nil, "192.0.2.1")
+	require.NoError(t, err)
+	assert.Equal(t, clk.Now().Add(15*time.Minute), record.ExpiresAt)
+
+	// 缓存和数据库中的验证码都已过期
+	clk.Advance(15*time.Minute + time.Second)
+	_, err = service.VerifyEmailCode(ctx, "dave@example.com", models.VerificationTypeRegister, mailer.code)
+	assert.Error(t, err)
+
+	// 过期验证码不再计入5分钟内的频率限制
+	for i := 0; i < 3; i++ {
+		_, err = service.GenerateEmailCode(ctx, "dave@example.com", models.VerificationTypeRegister, nil, "192.0.2.1")
+		require.NoError(t, err)
+	}
+	_, err = service.GenerateEmailCode(ctx, "dave@example.com", models.VerificationTypeRegister, nil, "192.0.2.1")
+	assert.Error(t, err)
+
+	verified, err := service.VerifyEmailCode(ctx, "dave@example.com", models.VerificationTypeRegister, mailer.code)
+	require.NoError(t, err)
+	assert.Equal(t, clk.Now().Add(15*time.Minute), verified.ExpiresAt)
+}
+
+// wrongCode 返回与code不同的6位验证码
+func wrongCode(code string) string {
+	if code == "000000" {
+		return "111111"
+	}
+	return "000000"
+}
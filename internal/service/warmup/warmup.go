@@ -0,0 +1,143 @@
+package warmup
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"sort"
+	"strings"
+	"sync"
+
+	"cloudpan/internal/pkg/cache"
+	"cloudpan/internal/pkg/config"
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+)
+
+// 参考数据源名称
+const (
+	SourcePlans             = "plans"              // 套餐相关的存储设置(默认配额、单文件上限等)
+	SourcePolicies          = "policies"           // 启用中的存储策略
+	SourceFeatureFlags      = "feature_flags"      // 功能特性标记
+	SourceReservedUsernames = "reserved_usernames" // 保留用户名
+	SourceMimeTypes         = "mime_types"         // 允许上传的MIME类型及扩展名
+)
+
+// SettingReader 按分类读取系统设置，由系统设置仓储实现
+type SettingReader interface {
+	ListByCategory(ctx context.Context, category string) ([]*models.SystemSetting, error)
+}
+
+// PolicyReader 读取启用的存储策略，由存储策略仓储实现
+type PolicyReader interface {
+	ListActive(ctx context.Context) ([]*models.StoragePolicy, error)
+}
+
+// FlagReader 读取特性标记，由特性标记仓储实现
+type FlagReader interface {
+	ListAll(ctx context.Context) ([]*models.FeatureFlag, error)
+}
+
+// Repositories 参考数据来源，为nil的仓储对应的数据源不会注册
+type Repositories struct {
+	Settings SettingReader
+	Policies PolicyReader
+	Flags    FlagReader
+}
+
+// RegisterReferenceSources 向预热器注册全部热点参考数据源
+//
+// 使用示例：
+//
+//	warmer := cache.NewWarmer(cacheManager, logger)
+//	warmup.RegisterReferenceSources(warmer, warmup.Repositories{
+//		Settings: systemrepo.NewSettingRepository(db),
+//		Policies: systemrepo.NewStoragePolicyRepository(db),
+//		Flags:    systemrepo.NewFeatureFlagRepository(db),
+//	})
+//	report := warmer.WarmAll(ctx)
+func RegisterReferenceSources(warmer *cache.Warmer, repos Repositories) {
+	if repos.Settings != nil {
+		warmer.Register(cache.WarmSource{Name: SourcePlans, Load: loadPlans(repos.Settings)})
+	}
+	if repos.Policies != nil {
+		warmer.Register(cache.WarmSource{Name: SourcePolicies, Load: func(ctx context.Context) (interface{}, error) {
+			return repos.Policies.ListActive(ctx)
+		}})
+	}
+	if repos.Flags != nil {
+		warmer.Register(cache.WarmSource{Name: SourceFeatureFlags, Load: func(ctx context.Context) (interface{}, error) {
+			return repos.Flags.ListAll(ctx)
+		}})
+	}
+	warmer.Register(cache.WarmSource{Name: SourceReservedUsernames, Load: func(context.Context) (interface{}, error) {
+		names := utils.ReservedUsernames()
+		sort.Strings(names)
+		return names, nil
+	}})
+	warmer.Register(cache.WarmSource{Name: SourceMimeTypes, Load: func(context.Context) (interface{}, error) {
+		var allowed []string
+		if config.AppConfig != nil {
+			allowed = config.AppConfig.Storage.Local.AllowedTypes
+		}
+		return BuildMimeTypeMap(allowed), nil
+	}})
+}
+
+// loadPlans 读取存储分类的系统设置，按设置键组成映射
+func loadPlans(settings SettingReader) cache.WarmLoader {
+	return func(ctx context.Context) (interface{}, error) {
+		list, err := settings.ListByCategory(ctx, models.SettingCategoryStorage)
+		if err != nil {
+			return nil, fmt.Errorf("读取存储设置失败: %w", err)
+		}
+
+		plans := make(map[string]string, len(list))
+		for _, setting := range list {
+			plans[setting.Key] = setting.GetStringValue()
+		}
+		return plans, nil
+	}
+}
+
+// BuildMimeTypeMap 生成MIME类型到扩展名列表的映射
+//
+// 支持 image/* 形式的通配类型，通配类型和系统未登记的类型映射为空列表
+func BuildMimeTypeMap(allowedTypes []string) map[string][]string {
+	result := make(map[string][]string, len(allowedTypes))
+	for _, mimeType := range allowedTypes {
+		mimeType = strings.ToLower(strings.TrimSpace(mimeType))
+		if mimeType == "" {
+			continue
+		}
+
+		extensions := []string{}
+		if !strings.HasSuffix(mimeType, "/*") {
+			if exts, err := mime.ExtensionsByType(mimeType); err == nil && exts != nil {
+				extensions = exts
+			}
+		}
+		sort.Strings(extensions)
+		result[mimeType] = extensions
+	}
+	return result
+}
+
+var (
+	defaultMu     sync.RWMutex
+	defaultWarmer *cache.Warmer
+)
+
+// SetDefault 设置全局预热器，启动时由main调用
+func SetDefault(warmer *cache.Warmer) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultWarmer = warmer
+}
+
+// Default 返回全局预热器，未设置时返回nil
+func Default() *cache.Warmer {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultWarmer
+}
@@ -0,0 +1,82 @@
+package warmup
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"cloudpan/internal/pkg/cache"
+	"cloudpan/internal/repository/models"
+)
+
+// MockSettingReader 系统设置读取mock
+type MockSettingReader struct {
+	mock.Mock
+}
+
+func (m *MockSettingReader) ListByCategory(ctx context.Context, category string) ([]*models.SystemSetting, error) {
+	args := m.Called(ctx, category)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.SystemSetting), args.Error(1)
+}
+
+// MockFlagReader 特性标记读取mock
+type MockFlagReader struct {
+	mock.Mock
+}
+
+func (m *MockFlagReader) ListAll(ctx context.Context) ([]*models.FeatureFlag, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.FeatureFlag), args.Error(1)
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func TestRegisterReferenceSources(t *testing.T) {
+	ctx := context.Background()
+	settings := new(MockSettingReader)
+	settings.On("ListByCategory", mock.Anything, models.SettingCategoryStorage).Return([]*models.SystemSetting{
+		{Category: models.SettingCategoryStorage, Key: models.SettingKeyDefaultStorageQuota, Value: strPtr("10737418240")},
+	}, nil).Once()
+	flags := new(MockFlagReader)
+	flags.On("ListAll", mock.Anything).Return(nil, errors.New("db down")).Once()
+
+	warmer := cache.NewWarmer(nil, nil)
+	RegisterReferenceSources(warmer, Repositories{Settings: settings, Flags: flags})
+
+	assert.Equal(t, []string{SourceFeatureFlags, SourceMimeTypes, SourcePlans, SourceReservedUsernames}, warmer.Sources())
+
+	report := warmer.WarmAll(ctx)
+	assert.Equal(t, 1, report.Failed())
+
+	plans, ok := warmer.Get(ctx, SourcePlans)
+	require.True(t, ok)
+	assert.Equal(t, map[string]string{models.SettingKeyDefaultStorageQuota: "10737418240"}, plans)
+
+	reserved, ok := warmer.Get(ctx, SourceReservedUsernames)
+	require.True(t, ok)
+	assert.Contains(t, reserved, "admin")
+
+	settings.AssertExpectations(t)
+	flags.AssertExpectations(t)
+}
+
+func TestBuildMimeTypeMap(t *testing.T) {
+	result := BuildMimeTypeMap([]string{" Image/PNG ", "image/*", "application/x-unknown-type", ""})
+
+	assert.Len(t, result, 3)
+	assert.Contains(t, result["image/png"], ".png")
+	assert.Empty(t, result["image/*"])
+	assert.Empty(t, result["application/x-unknown-type"])
+}
@@ -0,0 +1,8 @@
+-- =============================================================
+-- 008_add_folder_checksum.down.sql
+-- 回滚：删除文件夹子树校验和字段
+-- =============================================================
+
+ALTER TABLE `files`
+  DROP COLUMN `checksum_updated_at`,
+  DROP COLUMN `content_checksum`;
@@ -0,0 +1,9 @@
+-- =============================================================
+-- 008_add_folder_checksum.sql
+-- 文件夹完整性校验
+-- 为文件表增加子树组合校验和字段，文件变更时沿父链清空以触发重新计算
+-- =============================================================
+
+ALTER TABLE `files`
+  ADD COLUMN `content_checksum` varchar(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci DEFAULT NULL COMMENT '文件夹子树组合校验和(为空表示需要重新计算)' AFTER `preview_url`,
+  ADD COLUMN `checksum_updated_at` timestamp NULL DEFAULT NULL COMMENT '校验和计算时间' AFTER `content_checksum`;
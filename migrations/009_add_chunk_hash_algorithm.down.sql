@@ -0,0 +1,7 @@
+-- =============================================================
+-- 009_add_chunk_hash_algorithm.down.sql
+-- 回滚：删除分片校验算法字段，进行中的CRC32C上传需重新开始
+-- =============================================================
+
+ALTER TABLE `file_upload_chunks`
+  DROP COLUMN `chunk_hash_algorithm`;
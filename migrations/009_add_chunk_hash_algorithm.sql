@@ -0,0 +1,8 @@
+-- =============================================================
+-- 009_add_chunk_hash_algorithm.sql
+-- 分片校验算法协商
+-- 为分片上传表增加校验算法字段，旧数据保持MD5
+-- =============================================================
+
+ALTER TABLE `file_upload_chunks`
+  ADD COLUMN `chunk_hash_algorithm` enum('md5','crc32c') CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT 'md5' COMMENT '分片校验算法(同一上传任务内保持一致)' AFTER `chunk_hash`;
@@ -0,0 +1,6 @@
+-- =============================================================
+-- 010_create_identifier_tombstones.down.sql
+-- 回滚：删除标识符墓碑表，已退役的UUID和分享码不再受复用保护
+-- =============================================================
+
+DROP TABLE IF EXISTS `identifier_tombstones`;
@@ -0,0 +1,47 @@
+-- =============================================================
+-- 010_create_identifier_tombstones.sql
+-- 标识符复用保护
+-- 创建标识符墓碑表，记录已物理删除记录的UUID和分享码，防止重新分配；
+-- 执行前检测现有数据(包括软删除记录)中的重复标识符，存在重复时中止迁移
+-- =============================================================
+
+-- 检测现有重复标识符
+DROP PROCEDURE IF EXISTS `check_identifier_duplicates`;
+
+DELIMITER $$
+CREATE PROCEDURE `check_identifier_duplicates`()
+BEGIN
+  DECLARE file_dups INT DEFAULT 0;
+  DECLARE share_dups INT DEFAULT 0;
+
+  SELECT COUNT(*) INTO file_dups FROM (
+    SELECT `uuid` FROM `files` GROUP BY `uuid` HAVING COUNT(*) > 1
+  ) d;
+
+  SELECT COUNT(*) INTO share_dups FROM (
+    SELECT `share_code` FROM `file_shares` GROUP BY `share_code` HAVING COUNT(*) > 1
+  ) d;
+
+  IF file_dups > 0 OR share_dups > 0 THEN
+    SIGNAL SQLSTATE '45000'
+      SET MESSAGE_TEXT = '存在重复的文件UUID或分享码(包括软删除记录)，请先处理后再执行迁移';
+  END IF;
+END$$
+DELIMITER ;
+
+CALL `check_identifier_duplicates`();
+DROP PROCEDURE IF EXISTS `check_identifier_duplicates`;
+
+-- 标识符墓碑表 (identifier_tombstones)
+CREATE TABLE `identifier_tombstones` (
+  `id` int unsigned NOT NULL AUTO_INCREMENT COMMENT '墓碑ID',
+  `kind` varchar(50) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '标识符类型',
+  `value` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '标识符值',
+  `source_table` varchar(100) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '来源表',
+  `source_id` int unsigned NOT NULL COMMENT '来源记录ID',
+  `retired_at` timestamp NOT NULL COMMENT '退役时间',
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_identifier_tombstones_kind_value` (`kind`,`value`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='标识符墓碑表';
@@ -0,0 +1,7 @@
+-- =============================================================
+-- 011_encrypt_sensitive_user_columns.down.sql
+-- 不可回滚：字段中已是应用层密文，放不下原字段长度，手机号索引也无法重建；
+-- 需要回到该版本之前时先从备份恢复 users 表，再用 -action force 设置版本
+-- =============================================================
+
+-- irreversible: users.phone and users.mfa_secret hold ciphertext that does not fit the original columns
@@ -0,0 +1,12 @@
+-- =============================================================
+-- 011_encrypt_sensitive_user_columns.sql
+-- 敏感字段加密存储
+-- 手机号、MFA密钥和备用码改为应用层加密(enc:<密钥版本>:<用户UUID>:<密文>)，
+-- 加宽字段以容纳密文(备用码为text类型无需调整)；密文使用随机nonce，手机号索引不再可用，予以删除。
+-- 执行后运行 go run ./cmd/migrate -action reencrypt 加密已有明文数据
+-- =============================================================
+
+ALTER TABLE `users`
+  DROP INDEX `idx_users_phone`,
+  MODIFY COLUMN `phone` varchar(512) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci DEFAULT NULL COMMENT '手机号(加密存储)',
+  MODIFY COLUMN `mfa_secret` varchar(512) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci DEFAULT NULL COMMENT 'MFA密钥(加密存储)';
@@ -0,0 +1,9 @@
+-- =============================================================
+-- 012_add_share_password_protection.down.sql
+-- 回滚：删除分享密码错误计数和锁定字段。
+-- 已哈希的分享密码无法还原为明文，回滚后旧版本应用不能验证这些密码
+-- =============================================================
+
+ALTER TABLE `file_shares`
+  DROP COLUMN `password_locked_until`,
+  DROP COLUMN `password_failed_attempts`;
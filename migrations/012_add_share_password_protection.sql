@@ -0,0 +1,11 @@
+-- =============================================================
+-- 012_add_share_password_protection.sql
+-- 分享密码保护
+-- 分享密码改为BCrypt哈希存储，并记录连续错误次数和临时锁定时间。
+-- 执行后运行 go run ./cmd/migrate -action hash-share-passwords 哈希已有明文密码，
+-- 未迁移的明文密码在首次验证成功时也会由应用自动升级为哈希
+-- =============================================================
+
+ALTER TABLE `file_shares`
+  ADD COLUMN `password_failed_attempts` int NOT NULL DEFAULT '0' COMMENT '连续密码错误次数' AFTER `password`,
+  ADD COLUMN `password_locked_until` timestamp NULL DEFAULT NULL COMMENT '密码验证锁定截止时间' AFTER `password_failed_attempts`;
@@ -0,0 +1,8 @@
+-- =============================================================
+-- 013_add_share_transfer_quota.down.sql
+-- 回滚：删除分享流量统计字段
+-- =============================================================
+
+ALTER TABLE `file_shares`
+  DROP COLUMN `transfer_period_start`,
+  DROP COLUMN `transfer_used`;
@@ -0,0 +1,10 @@
+-- =============================================================
+-- 013_add_share_transfer_quota.sql
+-- 分享流量配额
+-- 按自然月统计每个分享链接消耗的下载流量，超过套餐上限后暂停下载，
+-- 避免单个热门链接耗尽出口带宽预算
+-- =============================================================
+
+ALTER TABLE `file_shares`
+  ADD COLUMN `transfer_used` bigint NOT NULL DEFAULT '0' COMMENT '当前周期已消耗的下载流量(字节)' AFTER `download_count`,
+  ADD COLUMN `transfer_period_start` timestamp NULL DEFAULT NULL COMMENT '当前流量统计周期的开始时间' AFTER `transfer_used`;
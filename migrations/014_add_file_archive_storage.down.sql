@@ -0,0 +1,12 @@
+-- =============================================================
+-- 014_add_file_archive_storage.down.sql
+-- 回滚：删除归档存储字段。
+-- 回滚前须先恢复已归档的对象，否则旧版本应用会直接读取归档对象而失败
+-- =============================================================
+
+ALTER TABLE `files`
+  DROP INDEX `idx_files_archive_candidates`,
+  DROP COLUMN `restored_until`,
+  DROP COLUMN `restore_requested_at`,
+  DROP COLUMN `archived_at`,
+  DROP COLUMN `storage_class`;
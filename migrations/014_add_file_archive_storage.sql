@@ -0,0 +1,13 @@
+-- =============================================================
+-- 014_add_file_archive_storage.sql
+-- 归档存储
+-- 长期未访问的对象存储文件转换为归档存储类型(S3 Glacier、OSS归档存储)以降低成本，
+-- 归档文件需先发起恢复，恢复完成后在保留期内可下载
+-- =============================================================
+
+ALTER TABLE `files`
+  ADD COLUMN `storage_class` varchar(32) NOT NULL DEFAULT 'STANDARD' COMMENT '对象存储类型(STANDARD/GLACIER/DEEP_ARCHIVE等)' AFTER `storage_bucket`,
+  ADD COLUMN `archived_at` timestamp NULL DEFAULT NULL COMMENT '归档时间，为空表示未归档' AFTER `storage_class`,
+  ADD COLUMN `restore_requested_at` timestamp NULL DEFAULT NULL COMMENT '最近一次发起恢复的时间' AFTER `archived_at`,
+  ADD COLUMN `restored_until` timestamp NULL DEFAULT NULL COMMENT '恢复副本可读取的截止时间' AFTER `restore_requested_at`,
+  ADD INDEX `idx_files_archive_candidates` (`archived_at`, `status`, `storage_type`);
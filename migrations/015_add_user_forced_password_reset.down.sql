@@ -0,0 +1,9 @@
+-- =============================================================
+-- 015_add_user_forced_password_reset.down.sql
+-- 回滚：删除强制重置密码标记，被标记的用户恢复正常登录
+-- =============================================================
+
+ALTER TABLE `users`
+  DROP INDEX `idx_users_password_reset_required`,
+  DROP COLUMN `password_reset_requested_at`,
+  DROP COLUMN `password_reset_required`;
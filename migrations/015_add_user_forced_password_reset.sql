@@ -0,0 +1,11 @@
+-- =============================================================
+-- 015_add_user_forced_password_reset.sql
+-- 强制重置密码
+-- 安全事件后管理员批量标记用户必须重置密码，标记期间禁止登录和刷新令牌，
+-- 用户通过忘记密码流程设置新密码后清除标记
+-- =============================================================
+
+ALTER TABLE `users`
+  ADD COLUMN `password_reset_required` tinyint(1) NOT NULL DEFAULT '0' COMMENT '是否必须重置密码' AFTER `password_changed_at`,
+  ADD COLUMN `password_reset_requested_at` timestamp NULL DEFAULT NULL COMMENT '管理员要求重置密码的时间' AFTER `password_reset_required`,
+  ADD INDEX `idx_users_password_reset_required` (`password_reset_required`);
@@ -0,0 +1,7 @@
+-- =============================================================
+-- 016_rebuild_recycle_bin.down.sql
+-- 不可回滚：原回收站表与模型不一致，重建旧表会丢弃回收站记录，
+-- 回收站中文件的存储对象将无法清理、存储配额无法释放
+-- =============================================================
+
+-- irreversible: dropping recycle_bin would orphan trashed files and their storage objects
@@ -0,0 +1,60 @@
+-- =============================================================
+-- 016_rebuild_recycle_bin.sql
+-- 回收站
+-- 用户删除的文件和文件夹移入回收站，保留期内可以恢复到原位置，
+-- 过期后由应用的维护任务彻底删除存储对象并释放存储配额。
+-- 原回收站表与模型不一致且未被使用，按模型重建；数据库事件不再直接删除回收站记录，
+-- 避免文件记录和存储对象残留
+-- =============================================================
+
+DROP TABLE IF EXISTS `recycle_bin`;
+
+CREATE TABLE `recycle_bin` (
+  `id` int unsigned NOT NULL AUTO_INCREMENT COMMENT '回收站ID',
+  `uuid` char(36) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '回收站UUID',
+  `user_id` int unsigned NOT NULL COMMENT '用户ID',
+  `file_id` int unsigned NOT NULL COMMENT '被删除的文件或文件夹ID',
+  `original_name` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '原始文件名',
+  `original_path` varchar(2000) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '原始所在路径',
+  `original_parent_id` int unsigned DEFAULT NULL COMMENT '原始父文件夹ID',
+  `deleted_by` int unsigned NOT NULL COMMENT '删除者ID',
+  `trashed_at` datetime(3) NOT NULL COMMENT '移入回收站的时间',
+  `delete_reason` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci DEFAULT NULL COMMENT '删除原因',
+  `file_size` bigint DEFAULT '0' COMMENT '文件或文件夹内文件的总大小',
+  `is_folder` tinyint(1) DEFAULT '0' COMMENT '是否文件夹',
+  `is_restored` tinyint(1) DEFAULT '0' COMMENT '是否已恢复',
+  `restored_by` int unsigned DEFAULT NULL COMMENT '恢复者ID',
+  `restored_at` datetime(3) DEFAULT NULL COMMENT '恢复时间',
+  `restore_path` varchar(2000) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci DEFAULT NULL COMMENT '恢复后的路径',
+  `auto_delete_at` datetime(3) NOT NULL COMMENT '自动彻底删除时间',
+  `is_expired` tinyint(1) DEFAULT '0' COMMENT '是否已过期',
+  `metadata` json DEFAULT NULL COMMENT '附加元数据(本次移入的文件ID列表)',
+  `created_at` datetime(3) DEFAULT NULL COMMENT '创建时间',
+  `updated_at` datetime(3) DEFAULT NULL COMMENT '更新时间',
+  `deleted_at` datetime(3) DEFAULT NULL COMMENT '软删除时间',
+  `version` bigint DEFAULT '1' COMMENT '乐观锁版本号',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_recycle_bin_uuid` (`uuid`),
+  KEY `idx_recycle_bin_user_id` (`user_id`),
+  KEY `idx_recycle_bin_file_id` (`file_id`),
+  KEY `idx_recycle_bin_trashed_at` (`trashed_at`),
+  KEY `idx_recycle_bin_auto_delete_at` (`auto_delete_at`),
+  KEY `idx_recycle_bin_is_expired` (`is_expired`),
+  KEY `idx_recycle_bin_deleted_at` (`deleted_at`),
+  CONSTRAINT `fk_recycle_bin_file_id` FOREIGN KEY (`file_id`) REFERENCES `files` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `fk_recycle_bin_user_id` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `fk_recycle_bin_restored_by` FOREIGN KEY (`restored_by`) REFERENCES `users` (`id`) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='回收站表';
+
+-- 回收站过期清理改由应用完成，事件只保留其他清理
+DELIMITER $$
+ALTER EVENT daily_cleanup
+DO
+BEGIN
+    DELETE FROM verification_codes WHERE expires_at < NOW();
+    DELETE FROM password_reset_tokens WHERE expires_at < NOW();
+    DELETE FROM user_sessions WHERE expires_at < NOW();
+    DELETE FROM notifications WHERE created_at < DATE_SUB(NOW(), INTERVAL 30 DAY);
+    UPDATE file_shares SET status = 'expired' WHERE expires_at < NOW() AND status = 'active';
+END$$
+DELIMITER ;
@@ -0,0 +1,8 @@
+-- =============================================================
+-- 017_add_user_deletion_schedule.down.sql
+-- 回滚：删除账户计划删除时间，已申请注销的账户保持deleted状态
+-- =============================================================
+
+ALTER TABLE `users`
+  DROP INDEX `idx_users_deletion_scheduled_at`,
+  DROP COLUMN `deletion_scheduled_at`;
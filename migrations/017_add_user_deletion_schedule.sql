@@ -0,0 +1,10 @@
+-- =============================================================
+-- 017_add_user_deletion_schedule.sql
+-- 账户计划删除
+-- 申请注销的账户状态置为deleted并记录彻底删除的计划时间，
+-- 计划时间之前用户可通过登录凭据重新激活，之后由删除任务彻底删除
+-- =============================================================
+
+ALTER TABLE `users`
+  ADD COLUMN `deletion_scheduled_at` timestamp NULL DEFAULT NULL COMMENT '账户彻底删除的计划时间' AFTER `password_reset_requested_at`,
+  ADD INDEX `idx_users_deletion_scheduled_at` (`deletion_scheduled_at`);
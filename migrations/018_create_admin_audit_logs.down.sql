@@ -0,0 +1,9 @@
+-- =============================================================
+-- 018_create_admin_audit_logs.down.sql
+-- 回滚：删除管理员操作审计日志表及其只追加触发器。
+-- 表中的审计记录随之删除，有合规要求时回滚前先导出
+-- =============================================================
+
+DROP TRIGGER IF EXISTS `trg_admin_audit_logs_no_delete`;
+DROP TRIGGER IF EXISTS `trg_admin_audit_logs_no_update`;
+DROP TABLE IF EXISTS `admin_audit_logs`;
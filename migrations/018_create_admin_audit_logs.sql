@@ -0,0 +1,42 @@
+-- =============================================================
+-- 018_create_admin_audit_logs.sql
+-- 管理员操作审计日志
+-- 记录管理员的特权操作(停用用户、调整配额、模拟登录、修改特性开关等)的
+-- 操作者、操作对象、变更前后的值和原因，供合规审查查询。
+-- 日志只允许追加，触发器拒绝更新和删除
+-- =============================================================
+
+CREATE TABLE `admin_audit_logs` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '审计日志ID',
+  `actor_id` int unsigned NOT NULL COMMENT '操作的管理员ID',
+  `action` varchar(100) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '操作类型',
+  `target_type` varchar(50) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '操作对象类型',
+  `target_id` varchar(100) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '操作对象ID',
+  `reason` varchar(500) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci DEFAULT NULL COMMENT '操作原因',
+  `before` json DEFAULT NULL COMMENT '操作前的值',
+  `after` json DEFAULT NULL COMMENT '操作后的值',
+  `ip_address` varchar(45) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci DEFAULT NULL COMMENT 'IP地址',
+  `user_agent` varchar(1000) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci DEFAULT NULL COMMENT '用户代理',
+  `request_id` varchar(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci DEFAULT NULL COMMENT '请求ID',
+  `created_at` datetime(3) NOT NULL COMMENT '操作时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_admin_audit_logs_actor_id` (`actor_id`),
+  KEY `idx_admin_audit_logs_action` (`action`),
+  KEY `idx_admin_audit_logs_target` (`target_type`, `target_id`),
+  KEY `idx_admin_audit_logs_created_at` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='管理员操作审计日志表';
+
+-- 审计日志写入后不可修改或删除
+DELIMITER $$
+CREATE TRIGGER `trg_admin_audit_logs_no_update` BEFORE UPDATE ON `admin_audit_logs`
+FOR EACH ROW
+BEGIN
+    SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'admin_audit_logs is append-only';
+END$$
+
+CREATE TRIGGER `trg_admin_audit_logs_no_delete` BEFORE DELETE ON `admin_audit_logs`
+FOR EACH ROW
+BEGIN
+    SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'admin_audit_logs is append-only';
+END$$
+DELIMITER ;
@@ -0,0 +1,9 @@
+-- =============================================================
+-- 019_create_user_two_factors.down.sql
+-- 回滚：删除两步验证登记表，并关闭依赖该表的用户的MFA标记
+-- =============================================================
+
+UPDATE `users` SET `mfa_enabled` = 0
+WHERE `id` IN (SELECT `user_id` FROM `user_two_factors` WHERE `enabled` = 1);
+
+DROP TABLE IF EXISTS `user_two_factors`;
@@ -0,0 +1,21 @@
+-- =============================================================
+-- 019_create_user_two_factors.sql
+-- 两步验证(TOTP)登记
+-- 每个用户一条登记记录：TOTP密钥按用户派生的密钥加密存储，
+-- 备用码只保存SHA-256哈希；启用和关闭时同步更新 users.mfa_enabled
+-- =============================================================
+
+CREATE TABLE `user_two_factors` (
+  `id` int unsigned NOT NULL AUTO_INCREMENT COMMENT '登记ID',
+  `user_id` int unsigned NOT NULL COMMENT '用户ID',
+  `secret` varchar(512) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL COMMENT 'TOTP密钥(加密存储)',
+  `enabled` tinyint(1) DEFAULT '0' COMMENT '是否已启用',
+  `enabled_at` datetime(3) DEFAULT NULL COMMENT '启用时间',
+  `last_used_step` bigint DEFAULT '0' COMMENT '最近一次通过验证的时间步，用于防止验证码重放',
+  `backup_codes` text CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci COMMENT '未使用的备用码哈希(逗号分隔)',
+  `created_at` datetime(3) DEFAULT NULL COMMENT '创建时间',
+  `updated_at` datetime(3) DEFAULT NULL COMMENT '更新时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_user_two_factors_user_id` (`user_id`),
+  CONSTRAINT `fk_user_two_factors_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='用户两步验证登记表';
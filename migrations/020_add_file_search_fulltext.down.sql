@@ -0,0 +1,7 @@
+-- =============================================================
+-- 020_add_file_search_fulltext.down.sql
+-- 回滚：删除文件搜索FULLTEXT索引
+-- =============================================================
+
+ALTER TABLE `files`
+  DROP INDEX `ft_files_search`;
@@ -0,0 +1,9 @@
+-- =============================================================
+-- 020_add_file_search_fulltext.sql
+-- 文件搜索
+-- 在文件名、标签和描述上建立FULLTEXT索引，使用ngram分词以支持中文关键词，
+-- 文件搜索接口按相关度排序
+-- =============================================================
+
+ALTER TABLE `files`
+  ADD FULLTEXT INDEX `ft_files_search` (`name`, `tags`, `description`) WITH PARSER ngram;
@@ -0,0 +1,7 @@
+-- =============================================================
+-- 021_create_folder_rules.down.sql
+-- 回滚：删除文件夹自动化规则及执行日志
+-- =============================================================
+
+DROP TABLE IF EXISTS `folder_rule_logs`;
+DROP TABLE IF EXISTS `folder_rules`;
@@ -0,0 +1,53 @@
+-- =============================================================
+-- 021_create_folder_rules.sql
+-- 文件夹自动化规则
+-- 文件上传到设置了规则的文件夹后，条件表达式匹配时调用Webhook、
+-- 添加标签或移动到其他文件夹；每次执行记录一条执行日志，
+-- 日志超过保留期后由维护任务清理
+-- =============================================================
+
+CREATE TABLE `folder_rules` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '规则ID',
+  `created_at` datetime(3) DEFAULT NULL COMMENT '创建时间',
+  `updated_at` datetime(3) DEFAULT NULL COMMENT '更新时间',
+  `deleted_at` datetime(3) DEFAULT NULL COMMENT '删除时间',
+  `version` bigint DEFAULT '1' COMMENT '乐观锁版本号',
+  `user_id` int unsigned NOT NULL COMMENT '用户ID',
+  `folder_id` int unsigned NOT NULL COMMENT '规则所在文件夹ID',
+  `name` varchar(100) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '规则名称',
+  `event` varchar(50) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT 'file.uploaded' COMMENT '触发事件',
+  `condition` varchar(1000) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '' COMMENT '条件表达式，为空时总是执行',
+  `include_subfolders` tinyint(1) DEFAULT '0' COMMENT '是否对子文件夹中的文件生效',
+  `is_active` tinyint(1) DEFAULT '1' COMMENT '是否启用',
+  `action` varchar(20) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '动作类型(webhook/tag/move)',
+  `webhook_url` varchar(500) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci DEFAULT NULL COMMENT 'Webhook地址',
+  `webhook_secret` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci DEFAULT NULL COMMENT 'Webhook签名密钥',
+  `tags` varchar(500) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci DEFAULT NULL COMMENT '要添加的标签(逗号分隔)',
+  `target_folder_id` int unsigned DEFAULT NULL COMMENT '移动的目标文件夹ID',
+  `last_status` varchar(20) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci DEFAULT NULL COMMENT '最后一次执行结果',
+  `trigger_count` bigint DEFAULT '0' COMMENT '执行次数',
+  `failure_count` bigint DEFAULT '0' COMMENT '失败次数',
+  `last_triggered_at` datetime(3) DEFAULT NULL COMMENT '最后执行时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_folder_rules_user_id` (`user_id`),
+  KEY `idx_folder_rules_folder_id` (`folder_id`),
+  KEY `idx_folder_rules_is_active` (`is_active`),
+  KEY `idx_folder_rules_target_folder_id` (`target_folder_id`),
+  KEY `idx_folder_rules_deleted_at` (`deleted_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='文件夹自动化规则表';
+
+CREATE TABLE `folder_rule_logs` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '日志ID',
+  `rule_id` bigint unsigned NOT NULL COMMENT '规则ID',
+  `file_id` int unsigned NOT NULL COMMENT '触发规则的文件ID',
+  `file_name` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '触发时的文件名',
+  `action` varchar(20) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '动作类型',
+  `status` varchar(20) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '执行结果(success/failed)',
+  `message` varchar(1000) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci DEFAULT NULL COMMENT '结果说明',
+  `duration_ms` bigint DEFAULT '0' COMMENT '执行耗时(毫秒)',
+  `created_at` datetime(3) NOT NULL COMMENT '执行时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_folder_rule_logs_rule` (`rule_id`, `created_at`),
+  KEY `idx_folder_rule_logs_file_id` (`file_id`),
+  KEY `idx_folder_rule_logs_created_at` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='文件夹规则执行日志表';
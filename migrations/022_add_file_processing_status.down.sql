@@ -0,0 +1,10 @@
+-- =============================================================
+-- 022_add_file_processing_status.down.sql
+-- 回滚：删除文件处理流水线状态字段
+-- =============================================================
+
+ALTER TABLE `files`
+  DROP INDEX `idx_files_scan_status`,
+  DROP INDEX `idx_files_preview_status`,
+  DROP COLUMN `preview_status`,
+  DROP COLUMN `scan_status`;
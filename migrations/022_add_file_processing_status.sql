@@ -0,0 +1,16 @@
+-- =============================================================
+-- 022_add_file_processing_status.sql
+-- 文件处理流水线状态
+-- 记录上传后的病毒扫描和预览生成状态，与文件状态一起汇总为元数据中的
+-- processing 对象，文件列表可按状态筛选
+-- =============================================================
+
+ALTER TABLE `files`
+  ADD COLUMN `scan_status` varchar(20) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT 'pending' COMMENT '病毒扫描状态：pending/clean/infected/failed/skipped' AFTER `preview_url`,
+  ADD COLUMN `preview_status` varchar(20) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '' COMMENT '预览生成状态：pending/ready/failed，为空表示不生成预览' AFTER `scan_status`,
+  ADD INDEX `idx_files_scan_status` (`user_id`, `scan_status`),
+  ADD INDEX `idx_files_preview_status` (`user_id`, `preview_status`);
+
+-- 上线前已存在的文件没有经过扫描；已有缩略图的文件视为预览已生成
+UPDATE `files` SET `scan_status` = 'skipped' WHERE `is_folder` = 0;
+UPDATE `files` SET `preview_status` = 'ready' WHERE `is_folder` = 0 AND `thumbnail_url` IS NOT NULL;
@@ -0,0 +1,8 @@
+-- =============================================================
+-- 023_create_sso.down.sql
+-- 回滚：删除企业单点登录相关表，须先回滚依赖 sso_providers 的 025
+-- =============================================================
+
+DROP TABLE IF EXISTS `sso_identities`;
+DROP TABLE IF EXISTS `sso_domains`;
+DROP TABLE IF EXISTS `sso_providers`;
@@ -0,0 +1,64 @@
+-- =============================================================
+-- 023_create_sso.sql
+-- 企业单点登录(OIDC)
+-- 每个租户一个身份提供方，客户端密钥按租户派生的密钥加密存储；
+-- 租户通过DNS TXT记录验证邮箱域名后，该域名的用户按邮箱跳转到
+-- 身份提供方登录，首次登录即时开通账户，可禁止使用密码登录；
+-- sso_identities 以身份提供方的subject关联本地账户
+-- =============================================================
+
+CREATE TABLE `sso_providers` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '身份提供方ID',
+  `created_at` datetime(3) DEFAULT NULL COMMENT '创建时间',
+  `updated_at` datetime(3) DEFAULT NULL COMMENT '更新时间',
+  `deleted_at` datetime(3) DEFAULT NULL COMMENT '删除时间',
+  `version` bigint DEFAULT '1' COMMENT '乐观锁版本号',
+  `tenant` varchar(100) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '租户标识',
+  `name` varchar(100) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '显示名称',
+  `issuer` varchar(500) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '签发者地址',
+  `client_id` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '客户端ID',
+  `client_secret` varchar(1024) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci DEFAULT NULL COMMENT '客户端密钥(加密存储)',
+  `scopes` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '' COMMENT '额外申请的scope(空格分隔)',
+  `groups_claim` varchar(100) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT 'groups' COMMENT 'ID令牌中组列表的声明名',
+  `role_mappings` json DEFAULT NULL COMMENT '组到角色的映射',
+  `default_role` varchar(20) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT 'user' COMMENT '没有组匹配时的角色',
+  `jit_enabled` tinyint(1) NOT NULL DEFAULT '1' COMMENT '是否即时开通账户',
+  `enforce_sso` tinyint(1) NOT NULL DEFAULT '0' COMMENT '是否禁止已验证域名的用户使用密码登录',
+  `is_active` tinyint(1) NOT NULL DEFAULT '1' COMMENT '是否启用',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_sso_providers_tenant` (`tenant`),
+  KEY `idx_sso_providers_deleted_at` (`deleted_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='单点登录身份提供方表';
+
+CREATE TABLE `sso_domains` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '域名ID',
+  `provider_id` bigint unsigned NOT NULL COMMENT '身份提供方ID',
+  `domain` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '邮箱域名(小写)',
+  `verification_token` varchar(100) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL COMMENT 'DNS TXT记录中的验证值',
+  `verified_at` datetime(3) DEFAULT NULL COMMENT '验证时间',
+  `created_at` datetime(3) DEFAULT NULL COMMENT '创建时间',
+  `updated_at` datetime(3) DEFAULT NULL COMMENT '更新时间',
+  `version` bigint DEFAULT '1' COMMENT '乐观锁版本号',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_sso_domains_domain` (`domain`),
+  KEY `idx_sso_domains_provider_id` (`provider_id`),
+  CONSTRAINT `fk_sso_domains_provider` FOREIGN KEY (`provider_id`) REFERENCES `sso_providers` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='单点登录邮箱域名表';
+
+CREATE TABLE `sso_identities` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '关联ID',
+  `provider_id` bigint unsigned NOT NULL COMMENT '身份提供方ID',
+  `subject` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '身份提供方的用户标识(sub)',
+  `user_id` int unsigned NOT NULL COMMENT '本地用户ID',
+  `email` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci DEFAULT NULL COMMENT '最近一次登录时的邮箱',
+  `role` varchar(20) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT 'user' COMMENT '最近一次登录时映射的角色',
+  `last_login_at` datetime(3) DEFAULT NULL COMMENT '最近登录时间',
+  `created_at` datetime(3) DEFAULT NULL COMMENT '创建时间',
+  `updated_at` datetime(3) DEFAULT NULL COMMENT '更新时间',
+  `version` bigint DEFAULT '1' COMMENT '乐观锁版本号',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_sso_identities_provider_subject` (`provider_id`, `subject`),
+  KEY `idx_sso_identities_user_id` (`user_id`),
+  CONSTRAINT `fk_sso_identities_provider` FOREIGN KEY (`provider_id`) REFERENCES `sso_providers` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `fk_sso_identities_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='单点登录身份关联表';
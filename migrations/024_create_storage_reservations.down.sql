@@ -0,0 +1,6 @@
+-- =============================================================
+-- 024_create_storage_reservations.down.sql
+-- 回滚：删除上传存储空间预留表，未完成的预留随之失效
+-- =============================================================
+
+DROP TABLE IF EXISTS `storage_reservations`;
@@ -0,0 +1,22 @@
+-- =============================================================
+-- 024_create_storage_reservations.sql
+-- 上传存储空间预留
+-- 分片上传和浏览器直传开始时按声明大小预留配额，完成时删除预留并累计
+-- users.storage_used，失败时删除预留；过期的预留不再占用配额，由维护任务清理
+-- =============================================================
+
+CREATE TABLE `storage_reservations` (
+  `id` int unsigned NOT NULL AUTO_INCREMENT COMMENT '预留ID',
+  `user_id` int unsigned NOT NULL COMMENT '用户ID',
+  `upload_id` varchar(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '上传任务ID',
+  `size` bigint NOT NULL COMMENT '预留大小(字节)',
+  `expires_at` datetime(3) NOT NULL COMMENT '过期时间',
+  `created_at` datetime(3) DEFAULT NULL COMMENT '创建时间',
+  `updated_at` datetime(3) DEFAULT NULL COMMENT '更新时间',
+  `version` bigint DEFAULT '1' COMMENT '乐观锁版本号',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_storage_reservations_upload_id` (`upload_id`),
+  KEY `idx_storage_reservations_user_id` (`user_id`),
+  KEY `idx_storage_reservations_expires_at` (`expires_at`),
+  CONSTRAINT `fk_storage_reservations_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='上传存储空间预留表';
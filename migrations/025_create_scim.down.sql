@@ -0,0 +1,8 @@
+-- =============================================================
+-- 025_create_scim.down.sql
+-- 回滚：删除SCIM目录同步相关表，已开通的本地账户和团队保留
+-- =============================================================
+
+DROP TABLE IF EXISTS `scim_groups`;
+DROP TABLE IF EXISTS `scim_users`;
+DROP TABLE IF EXISTS `scim_tokens`;
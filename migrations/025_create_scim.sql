@@ -0,0 +1,57 @@
+-- =============================================================
+-- 025_create_scim.sql
+-- 企业目录同步(SCIM 2.0)
+-- 身份提供方(Okta、Azure AD等)使用各自的令牌调用SCIM接口开通、
+-- 更新、停用账户并同步组；scim_users 记录身份提供方开通或关联的
+-- 本地账户，scim_groups 中每个组对应一个团队，组成员同步为团队成员
+-- =============================================================
+
+CREATE TABLE `scim_tokens` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '令牌ID',
+  `provider_id` bigint unsigned NOT NULL COMMENT '身份提供方ID',
+  `token_hash` char(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '令牌SHA-256(十六进制)',
+  `token_prefix` varchar(16) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '令牌前缀',
+  `created_by` int unsigned NOT NULL COMMENT '签发令牌的管理员ID',
+  `last_used_at` datetime(3) DEFAULT NULL COMMENT '最近使用时间',
+  `created_at` datetime(3) DEFAULT NULL COMMENT '创建时间',
+  `updated_at` datetime(3) DEFAULT NULL COMMENT '更新时间',
+  `version` bigint DEFAULT '1' COMMENT '乐观锁版本号',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_scim_tokens_provider_id` (`provider_id`),
+  UNIQUE KEY `idx_scim_tokens_token_hash` (`token_hash`),
+  CONSTRAINT `fk_scim_tokens_provider` FOREIGN KEY (`provider_id`) REFERENCES `sso_providers` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='目录同步令牌表';
+
+CREATE TABLE `scim_users` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '关联ID',
+  `provider_id` bigint unsigned NOT NULL COMMENT '身份提供方ID',
+  `user_id` int unsigned NOT NULL COMMENT '本地用户ID',
+  `user_name` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '身份提供方的userName(小写)',
+  `external_id` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci DEFAULT NULL COMMENT '身份提供方的externalId',
+  `created_at` datetime(3) DEFAULT NULL COMMENT '创建时间',
+  `updated_at` datetime(3) DEFAULT NULL COMMENT '更新时间',
+  `version` bigint DEFAULT '1' COMMENT '乐观锁版本号',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_scim_users_provider_user` (`provider_id`, `user_id`),
+  UNIQUE KEY `idx_scim_users_provider_user_name` (`provider_id`, `user_name`),
+  KEY `idx_scim_users_user_id` (`user_id`),
+  KEY `idx_scim_users_external_id` (`external_id`),
+  CONSTRAINT `fk_scim_users_provider` FOREIGN KEY (`provider_id`) REFERENCES `sso_providers` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `fk_scim_users_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='目录同步用户表';
+
+CREATE TABLE `scim_groups` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '组ID',
+  `provider_id` bigint unsigned NOT NULL COMMENT '身份提供方ID',
+  `team_id` int unsigned NOT NULL COMMENT '团队ID',
+  `display_name` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '组名称',
+  `external_id` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci DEFAULT NULL COMMENT '身份提供方的externalId',
+  `created_at` datetime(3) DEFAULT NULL COMMENT '创建时间',
+  `updated_at` datetime(3) DEFAULT NULL COMMENT '更新时间',
+  `version` bigint DEFAULT '1' COMMENT '乐观锁版本号',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_scim_groups_team_id` (`team_id`),
+  UNIQUE KEY `idx_scim_groups_provider_name` (`provider_id`, `display_name`),
+  CONSTRAINT `fk_scim_groups_provider` FOREIGN KEY (`provider_id`) REFERENCES `sso_providers` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `fk_scim_groups_team` FOREIGN KEY (`team_id`) REFERENCES `teams` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='目录同步组表';
@@ -0,0 +1,21 @@
+-- =============================================================
+-- 026_align_team_roles.down.sql
+-- 回滚：恢复 owner/admin/editor/viewer/guest 角色。
+-- member 还原为 editor；原 guest 已并入 viewer，无法区分，保持 viewer
+-- =============================================================
+
+ALTER TABLE `team_members`
+  MODIFY COLUMN `role` enum('owner','admin','editor','member','viewer','guest') CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci DEFAULT 'viewer' COMMENT '成员角色';
+
+UPDATE `team_members` SET `role` = 'editor' WHERE `role` = 'member';
+
+ALTER TABLE `team_members`
+  MODIFY COLUMN `role` enum('owner','admin','editor','viewer','guest') CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci DEFAULT 'viewer' COMMENT '成员角色';
+
+ALTER TABLE `team_invitations`
+  MODIFY COLUMN `role` enum('admin','editor','member','viewer','guest') CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci DEFAULT 'viewer' COMMENT '邀请角色';
+
+UPDATE `team_invitations` SET `role` = 'editor' WHERE `role` = 'member';
+
+ALTER TABLE `team_invitations`
+  MODIFY COLUMN `role` enum('admin','editor','viewer','guest') CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci DEFAULT 'viewer' COMMENT '邀请角色';
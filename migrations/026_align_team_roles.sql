@@ -0,0 +1,25 @@
+-- =============================================================
+-- 026_align_team_roles.sql
+-- 团队成员角色
+-- 团队成员角色统一为 owner/admin/member/viewer：owner和admin管理成员，
+-- member可以向团队共享文件，viewer只能查看团队文件。
+-- 原 editor 角色并入 member，guest 角色并入 viewer
+-- =============================================================
+
+ALTER TABLE `team_members`
+  MODIFY COLUMN `role` enum('owner','admin','editor','member','viewer','guest') CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci DEFAULT 'member' COMMENT '成员角色';
+
+UPDATE `team_members` SET `role` = 'member' WHERE `role` = 'editor';
+UPDATE `team_members` SET `role` = 'viewer' WHERE `role` = 'guest';
+
+ALTER TABLE `team_members`
+  MODIFY COLUMN `role` enum('owner','admin','member','viewer') CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci DEFAULT 'member' COMMENT '成员角色';
+
+ALTER TABLE `team_invitations`
+  MODIFY COLUMN `role` enum('admin','editor','member','viewer','guest') CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci DEFAULT 'member' COMMENT '邀请角色';
+
+UPDATE `team_invitations` SET `role` = 'member' WHERE `role` = 'editor';
+UPDATE `team_invitations` SET `role` = 'viewer' WHERE `role` = 'guest';
+
+ALTER TABLE `team_invitations`
+  MODIFY COLUMN `role` enum('admin','member','viewer') CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci DEFAULT 'member' COMMENT '邀请角色';
@@ -0,0 +1,6 @@
+-- =============================================================
+-- 027_create_file_grants.down.sql
+-- 回滚：删除文件和文件夹访问授权表
+-- =============================================================
+
+DROP TABLE IF EXISTS `file_grants`;
@@ -0,0 +1,22 @@
+-- =============================================================
+-- 027_create_file_grants.sql
+-- 文件和文件夹访问授权
+-- 管理员可将文件或文件夹的 read/write/share/delete 权限授予用户或团队，
+-- 文件夹上的授权对其下全部文件和子文件夹生效；文件删除时授权一并删除
+-- =============================================================
+
+CREATE TABLE `file_grants` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '授权ID',
+  `file_id` int unsigned NOT NULL COMMENT '文件或文件夹ID',
+  `subject_type` enum('user','team') CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '授权对象类型',
+  `subject_id` int unsigned NOT NULL COMMENT '授权对象ID(用户ID或团队ID)',
+  `permissions` varchar(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '权限，逗号分隔',
+  `granted_by` int unsigned NOT NULL COMMENT '授权的管理员ID',
+  `created_at` datetime(3) DEFAULT NULL COMMENT '创建时间',
+  `updated_at` datetime(3) DEFAULT NULL COMMENT '更新时间',
+  `version` bigint DEFAULT '1' COMMENT '乐观锁版本号',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_file_grants_subject` (`file_id`, `subject_type`, `subject_id`),
+  KEY `idx_file_grants_lookup` (`subject_type`, `subject_id`),
+  CONSTRAINT `fk_file_grants_file` FOREIGN KEY (`file_id`) REFERENCES `files` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='文件访问授权表';
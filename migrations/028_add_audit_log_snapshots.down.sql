@@ -0,0 +1,9 @@
+-- =============================================================
+-- 028_add_audit_log_snapshots.down.sql
+-- 回滚：删除审计日志快照字段和按用户、时间的联合索引
+-- =============================================================
+
+ALTER TABLE `audit_logs`
+  DROP INDEX `idx_audit_logs_user_created`,
+  DROP COLUMN `after`,
+  DROP COLUMN `before`;
@@ -0,0 +1,11 @@
+-- =============================================================
+-- 028_add_audit_log_snapshots.sql
+-- 安全审计日志
+-- 审计日志记录登录、修改和重置密码、创建分享、权限变更和删除等安全相关操作，
+-- 增加操作前后的快照字段，并按用户和时间建立联合索引供管理员按用户查询
+-- =============================================================
+
+ALTER TABLE `audit_logs`
+  ADD COLUMN `before` json DEFAULT NULL COMMENT '操作前的快照' AFTER `details`,
+  ADD COLUMN `after` json DEFAULT NULL COMMENT '操作后的快照' AFTER `before`,
+  ADD KEY `idx_audit_logs_user_created` (`user_id`, `created_at`);
@@ -0,0 +1,6 @@
+-- =============================================================
+-- 029_create_retained_objects.down.sql
+-- 回滚：删除保留存储对象登记表，回滚前应确认保留期内的对象已另行处理
+-- =============================================================
+
+DROP TABLE IF EXISTS `retained_objects`;
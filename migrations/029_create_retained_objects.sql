@@ -0,0 +1,38 @@
+-- =============================================================
+-- 029_create_retained_objects.sql
+-- 删除后保留的存储对象
+-- 回收站项目彻底删除时文件记录立即物理删除并释放存储配额，存储对象按合规要求
+-- (按租户、套餐配置)额外保留一段时间，到期后由维护任务删除。保留期内管理员
+-- 可以根据这里登记的信息重新创建文件记录，恢复到原用户的根目录。
+-- 不设置用户外键，用户被删除后保留记录仍然存在，到期时存储对象照常删除
+-- =============================================================
+
+CREATE TABLE `retained_objects` (
+  `id` int unsigned NOT NULL AUTO_INCREMENT COMMENT '保留记录ID',
+  `user_id` int unsigned NOT NULL COMMENT '原文件所属用户ID',
+  `file_id` int unsigned NOT NULL COMMENT '原文件ID(文件记录已删除)',
+  `name` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '原文件名',
+  `original_path` varchar(2000) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '原完整路径',
+  `size` bigint NOT NULL DEFAULT '0' COMMENT '文件大小(字节)',
+  `mime_type` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci DEFAULT NULL COMMENT 'MIME类型',
+  `hash` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci DEFAULT NULL COMMENT '文件哈希值',
+  `hash_type` varchar(16) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci DEFAULT NULL COMMENT '哈希类型',
+  `storage_type` varchar(16) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '存储类型',
+  `storage_path` varchar(2000) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '存储路径',
+  `storage_bucket` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci DEFAULT NULL COMMENT '存储桶名称',
+  `scan_status` varchar(20) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT 'pending' COMMENT '病毒扫描状态',
+  `is_encrypted` tinyint(1) DEFAULT '0' COMMENT '是否加密',
+  `encryption_key` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci DEFAULT NULL COMMENT '加密密钥',
+  `purged_at` datetime(3) NOT NULL COMMENT '文件记录删除时间',
+  `delete_after` datetime(3) NOT NULL COMMENT '存储对象删除时间',
+  `restored_file_id` int unsigned DEFAULT NULL COMMENT '恢复后的文件ID',
+  `restored_by` int unsigned DEFAULT NULL COMMENT '执行恢复的管理员ID',
+  `restored_at` datetime(3) DEFAULT NULL COMMENT '恢复时间',
+  `created_at` datetime(3) DEFAULT NULL COMMENT '创建时间',
+  `updated_at` datetime(3) DEFAULT NULL COMMENT '更新时间',
+  `version` bigint DEFAULT '1' COMMENT '乐观锁版本号',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_retained_objects_file_id` (`file_id`),
+  KEY `idx_retained_objects_user_id` (`user_id`),
+  KEY `idx_retained_objects_delete_after` (`delete_after`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='删除后保留的存储对象表';
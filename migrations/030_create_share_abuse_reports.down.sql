@@ -0,0 +1,11 @@
+-- =============================================================
+-- 030_create_share_abuse_reports.down.sql
+-- 回滚：删除分享举报表，仍处于暂停状态的分享改为停用，回滚前应先处理审核队列
+-- =============================================================
+
+UPDATE `file_shares` SET `status` = 'disabled' WHERE `status` = 'suspended';
+
+ALTER TABLE `file_shares`
+  MODIFY COLUMN `status` enum('active','expired','disabled','deleted') CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci DEFAULT 'active' COMMENT '分享状态';
+
+DROP TABLE IF EXISTS `share_abuse_reports`;
@@ -0,0 +1,33 @@
+-- =============================================================
+-- 030_create_share_abuse_reports.sql
+-- 公开分享举报
+-- 访问者可以按原因类别举报公开分享，同一分享的待审核举报来自足够多的不同IP时
+-- 自动暂停分享(状态 suspended)，管理员在审核队列中驳回(恢复分享)或确认违规(停用分享)。
+-- 不设置分享外键，分享被删除后举报记录仍保留备查
+-- =============================================================
+
+ALTER TABLE `file_shares`
+  MODIFY COLUMN `status` enum('active','expired','disabled','suspended','deleted') CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci DEFAULT 'active' COMMENT '分享状态';
+
+CREATE TABLE `share_abuse_reports` (
+  `id` int unsigned NOT NULL AUTO_INCREMENT COMMENT '举报ID',
+  `share_id` int unsigned NOT NULL COMMENT '分享ID',
+  `sharer_id` int unsigned NOT NULL COMMENT '分享者ID',
+  `share_code` varchar(100) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '举报时的分享码',
+  `reason` varchar(20) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '举报原因类别',
+  `details` varchar(1000) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci DEFAULT NULL COMMENT '补充说明',
+  `reporter_id` int unsigned DEFAULT NULL COMMENT '举报人ID(未登录时为空)',
+  `reporter_ip` varchar(45) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '举报人IP',
+  `status` varchar(20) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT 'pending' COMMENT '审核状态',
+  `reviewed_by` int unsigned DEFAULT NULL COMMENT '审核管理员ID',
+  `reviewed_at` datetime(3) DEFAULT NULL COMMENT '审核时间',
+  `review_note` varchar(500) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci DEFAULT NULL COMMENT '审核备注',
+  `created_at` datetime(3) DEFAULT NULL COMMENT '创建时间',
+  `updated_at` datetime(3) DEFAULT NULL COMMENT '更新时间',
+  `version` bigint DEFAULT '1' COMMENT '乐观锁版本号',
+  PRIMARY KEY (`id`),
+  KEY `idx_share_abuse_reports_share_status` (`share_id`, `status`),
+  KEY `idx_share_abuse_reports_status` (`status`),
+  KEY `idx_share_abuse_reports_sharer_id` (`sharer_id`),
+  KEY `idx_share_abuse_reports_reporter_id` (`reporter_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='公开分享举报表';
@@ -0,0 +1,6 @@
+-- =============================================================
+-- 031_create_content_moderation_results.down.sql
+-- 回滚：删除内容审核结果表，复核禁止的文件恢复为可以分享，已停用的分享保持停用
+-- =============================================================
+
+DROP TABLE IF EXISTS `content_moderation_results`;
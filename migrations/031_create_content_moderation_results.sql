@@ -0,0 +1,31 @@
+-- =============================================================
+-- 031_create_content_moderation_results.sql
+-- 分享图片内容审核结果
+-- 公开分享图片前调用内容审核接口，每个文件保留一条结果，文件内容变化后重新审核；
+-- 疑似违规(flagged)和违规(blocked)的结果进入管理员复核队列，复核结论(approved/rejected)优先于自动结论。
+-- 不设置文件外键，文件被删除后审核记录仍保留备查
+-- =============================================================
+
+CREATE TABLE `content_moderation_results` (
+  `id` int unsigned NOT NULL AUTO_INCREMENT COMMENT '审核结果ID',
+  `file_id` int unsigned NOT NULL COMMENT '文件ID',
+  `user_id` int unsigned NOT NULL COMMENT '文件所有者ID',
+  `file_name` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '审核时的文件名',
+  `file_hash` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '' COMMENT '审核时的文件哈希',
+  `status` varchar(20) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '审核结论',
+  `label` varchar(50) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '' COMMENT '置信度最高的标签',
+  `score` double NOT NULL DEFAULT '0' COMMENT '最高置信度',
+  `labels` json DEFAULT NULL COMMENT '全部标签的置信度',
+  `scanned_at` datetime(3) NOT NULL COMMENT '审核时间',
+  `override` varchar(20) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci DEFAULT NULL COMMENT '复核结论',
+  `reviewed_by` int unsigned DEFAULT NULL COMMENT '复核管理员ID',
+  `reviewed_at` datetime(3) DEFAULT NULL COMMENT '复核时间',
+  `review_note` varchar(500) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci DEFAULT NULL COMMENT '复核备注',
+  `created_at` datetime(3) DEFAULT NULL COMMENT '创建时间',
+  `updated_at` datetime(3) DEFAULT NULL COMMENT '更新时间',
+  `version` bigint DEFAULT '1' COMMENT '乐观锁版本号',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_content_moderation_results_file_id` (`file_id`),
+  KEY `idx_content_moderation_results_user_id` (`user_id`),
+  KEY `idx_content_moderation_results_status` (`status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='分享图片内容审核结果表';
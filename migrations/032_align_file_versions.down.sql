@@ -0,0 +1,15 @@
+-- =============================================================
+-- 032_align_file_versions.down.sql
+-- 回滚：删除补充的版本字段；已登记的版本没有uuid，回滚前需要先清空版本表
+-- =============================================================
+
+ALTER TABLE `file_versions`
+  DROP INDEX `idx_file_versions_deleted_at`,
+  DROP COLUMN `version`,
+  DROP COLUMN `deleted_at`,
+  DROP COLUMN `updated_at`,
+  DROP COLUMN `change_log`,
+  DROP COLUMN `mime_type`,
+  MODIFY COLUMN `storage_path` varchar(1000) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '存储路径',
+  MODIFY COLUMN `hash` varchar(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '文件哈希值',
+  MODIFY COLUMN `uuid` char(36) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '版本UUID';
@@ -0,0 +1,18 @@
+-- =============================================================
+-- 032_align_file_versions.sql
+-- 文件历史版本
+-- 覆盖上传和恢复版本时把文件原来的内容登记为历史版本，按文件保留有限个数，
+-- 超出的版本连同存储对象一起删除。版本表字段与 models.FileVersion 对齐：
+-- 补充MIME类型、变更说明和通用的更新时间、软删除、乐观锁字段，uuid不再使用
+-- =============================================================
+
+ALTER TABLE `file_versions`
+  MODIFY COLUMN `uuid` char(36) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci DEFAULT NULL COMMENT '版本UUID(未使用)',
+  MODIFY COLUMN `hash` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '' COMMENT '文件哈希值',
+  MODIFY COLUMN `storage_path` varchar(2000) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '存储路径',
+  ADD COLUMN `mime_type` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci DEFAULT NULL COMMENT 'MIME类型' AFTER `storage_path`,
+  ADD COLUMN `change_log` text CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci COMMENT '变更说明' AFTER `metadata`,
+  ADD COLUMN `updated_at` timestamp NULL DEFAULT NULL COMMENT '更新时间' AFTER `created_at`,
+  ADD COLUMN `deleted_at` timestamp NULL DEFAULT NULL COMMENT '删除时间' AFTER `updated_at`,
+  ADD COLUMN `version` bigint NOT NULL DEFAULT 1 COMMENT '乐观锁版本号' AFTER `deleted_at`,
+  ADD INDEX `idx_file_versions_deleted_at` (`deleted_at`);
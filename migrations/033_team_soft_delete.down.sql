@@ -0,0 +1,17 @@
+-- =============================================================
+-- 033_team_soft_delete.down.sql
+-- 回滚：归档中的团队和成员恢复为活跃状态后删除归档字段
+-- =============================================================
+
+UPDATE `team_members` SET `status` = 'active' WHERE `status` = 'archived';
+UPDATE `teams` SET `status` = 'active' WHERE `status` = 'archived';
+
+ALTER TABLE `team_members`
+  MODIFY COLUMN `status` enum('active','inactive','pending','rejected','left') CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci DEFAULT 'active' COMMENT '成员状态';
+
+ALTER TABLE `teams`
+  DROP INDEX `idx_teams_purge_at`,
+  DROP COLUMN `purge_at`,
+  DROP COLUMN `archived_by`,
+  DROP COLUMN `archived_at`,
+  MODIFY COLUMN `status` enum('active','suspended','deleted') CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci DEFAULT 'active' COMMENT '团队状态';
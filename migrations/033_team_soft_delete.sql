@@ -0,0 +1,17 @@
+-- =============================================================
+-- 033_team_soft_delete.sql
+-- 团队删除后归档
+-- 删除团队时团队状态改为archived并记录恢复期限，活跃成员的状态改为archived，
+-- 恢复期限内成员只能查看团队和团队文件，所有者可以恢复；过期后由维护任务彻底删除。
+-- 团队和成员状态取值与 models.Team、models.TeamMember 对齐
+-- =============================================================
+
+ALTER TABLE `teams`
+  MODIFY COLUMN `status` enum('active','inactive','suspended','archived','deleted') CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci DEFAULT 'active' COMMENT '团队状态',
+  ADD COLUMN `archived_at` timestamp NULL DEFAULT NULL COMMENT '删除(归档)时间' AFTER `status`,
+  ADD COLUMN `archived_by` bigint unsigned DEFAULT NULL COMMENT '删除团队的用户ID' AFTER `archived_at`,
+  ADD COLUMN `purge_at` timestamp NULL DEFAULT NULL COMMENT '恢复期限，之后彻底删除' AFTER `archived_by`,
+  ADD INDEX `idx_teams_purge_at` (`purge_at`);
+
+ALTER TABLE `team_members`
+  MODIFY COLUMN `status` enum('active','inactive','pending','suspended','rejected','left','archived') CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci DEFAULT 'active' COMMENT '成员状态';
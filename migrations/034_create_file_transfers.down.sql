@@ -0,0 +1,6 @@
+-- =============================================================
+-- 034_create_file_transfers.down.sql
+-- 回滚：删除文件所有权转移表
+-- =============================================================
+
+DROP TABLE IF EXISTS `file_transfers`;
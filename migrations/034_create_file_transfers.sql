@@ -0,0 +1,33 @@
+-- =============================================================
+-- 034_create_file_transfers.sql
+-- 文件和文件夹所有权转移
+-- 用户把文件或文件夹转给另一个用户，接收方接受后连同全部子项移到接收方名下，存储用量随之转移；
+-- 管理员可以为离职交接强制转移。记录保留作为转移历史，状态取值与 models.FileTransfer 对齐
+-- =============================================================
+
+CREATE TABLE `file_transfers` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '转移ID',
+  `file_id` int unsigned NOT NULL COMMENT '转移的文件或文件夹ID',
+  `file_name` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '文件名，完成后为接收方空间中的名称',
+  `is_folder` tinyint(1) DEFAULT '0' COMMENT '是否为文件夹',
+  `from_user_id` int unsigned NOT NULL COMMENT '原所有者ID',
+  `to_user_id` int unsigned NOT NULL COMMENT '接收方ID',
+  `status` enum('pending','accepted','completed','declined','cancelled','expired') CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT 'pending' COMMENT '转移状态',
+  `share_mode` enum('keep','revoke') CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT 'keep' COMMENT '已有分享的处理方式',
+  `message` varchar(500) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci DEFAULT NULL COMMENT '发起方留言或强制转移原因',
+  `forced_by` int unsigned DEFAULT NULL COMMENT '强制转移的管理员ID',
+  `items` bigint DEFAULT '0' COMMENT '转移的文件数(含文件夹)',
+  `size` bigint DEFAULT '0' COMMENT '转移的存储用量(字节，含历史版本)',
+  `error` varchar(500) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci DEFAULT NULL COMMENT '最近一次完成失败的原因',
+  `expires_at` datetime(3) DEFAULT NULL COMMENT '等待接受的截止时间',
+  `responded_at` datetime(3) DEFAULT NULL COMMENT '接受、拒绝或取消的时间',
+  `completed_at` datetime(3) DEFAULT NULL COMMENT '完成时间',
+  `created_at` datetime(3) DEFAULT NULL COMMENT '创建时间',
+  `updated_at` datetime(3) DEFAULT NULL COMMENT '更新时间',
+  `version` bigint DEFAULT '1' COMMENT '乐观锁版本号',
+  PRIMARY KEY (`id`),
+  KEY `idx_file_transfers_file_id` (`file_id`),
+  KEY `idx_file_transfers_from` (`from_user_id`, `status`),
+  KEY `idx_file_transfers_to` (`to_user_id`, `status`),
+  KEY `idx_file_transfers_expires_at` (`expires_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='文件所有权转移表';
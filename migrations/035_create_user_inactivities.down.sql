@@ -0,0 +1,6 @@
+-- =============================================================
+-- 035_create_user_inactivities.down.sql
+-- 回滚：删除长期未登录账户处置状态表
+-- =============================================================
+
+DROP TABLE IF EXISTS `user_inactivities`;
@@ -0,0 +1,23 @@
+-- =============================================================
+-- 035_create_user_inactivities.sql
+-- 长期未登录账户处置状态
+-- 账户长期未登录时按策略发送提醒邮件，然后降低存储配额或冻结账户，最后计划删除；
+-- 记录保存已执行的步骤和处置前的配额，用户重新登录时据此恢复并删除记录。步骤取值与 models.UserInactivity 对齐
+-- =============================================================
+
+CREATE TABLE `user_inactivities` (
+  `user_id` int unsigned NOT NULL COMMENT '用户ID',
+  `last_active_at` datetime(3) NOT NULL COMMENT '进入提醒期时用户的最后活动时间',
+  `step` varchar(20) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '下一步：action/deletion/done',
+  `step_due_at` datetime(3) DEFAULT NULL COMMENT '下一步的执行时间',
+  `warnings_sent` int DEFAULT '0' COMMENT '当前步骤已发送的提醒次数',
+  `last_warned_at` datetime(3) DEFAULT NULL COMMENT '最近一次发送提醒的时间',
+  `original_quota` bigint DEFAULT NULL COMMENT '降低配额前的存储配额',
+  `downgraded_at` datetime(3) DEFAULT NULL COMMENT '降低配额的时间',
+  `frozen_at` datetime(3) DEFAULT NULL COMMENT '冻结账户的时间',
+  `deletion_scheduled_at` datetime(3) DEFAULT NULL COMMENT '计划删除账户的时间',
+  `created_at` datetime(3) DEFAULT NULL COMMENT '进入提醒期的时间',
+  `updated_at` datetime(3) DEFAULT NULL COMMENT '更新时间',
+  PRIMARY KEY (`user_id`),
+  KEY `idx_user_inactivities_step_due_at` (`step_due_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='长期未登录账户处置状态表';
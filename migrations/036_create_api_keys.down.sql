@@ -0,0 +1,6 @@
+-- =============================================================
+-- 036_create_api_keys.down.sql
+-- 回滚：删除用户API密钥表
+-- =============================================================
+
+DROP TABLE IF EXISTS `api_keys`;
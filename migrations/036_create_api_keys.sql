@@ -0,0 +1,25 @@
+-- =============================================================
+-- 036_create_api_keys.sql
+-- 用户API密钥
+-- 用户为脚本和第三方工具创建的访问密钥，通过 X-API-Key 请求头认证；
+-- 只保存密钥的SHA-256哈希和前缀，权限范围(read/write/share)以逗号分隔保存，撤销时删除记录
+-- =============================================================
+
+CREATE TABLE `api_keys` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '密钥ID',
+  `user_id` int unsigned NOT NULL COMMENT '所有者用户ID',
+  `name` varchar(100) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '密钥名称',
+  `prefix` varchar(16) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '密钥前缀，用于识别密钥',
+  `key_hash` char(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '密钥SHA-256哈希',
+  `scopes` varchar(100) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '权限范围，逗号分隔',
+  `expires_at` datetime(3) DEFAULT NULL COMMENT '过期时间，为空表示不过期',
+  `last_used_at` datetime(3) DEFAULT NULL COMMENT '最后使用时间',
+  `last_used_ip` varchar(45) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci DEFAULT NULL COMMENT '最后使用的IP地址',
+  `created_at` datetime(3) DEFAULT NULL COMMENT '创建时间',
+  `updated_at` datetime(3) DEFAULT NULL COMMENT '更新时间',
+  `version` bigint DEFAULT '1' COMMENT '乐观锁版本号',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_api_keys_key_hash` (`key_hash`),
+  KEY `idx_api_keys_user_id` (`user_id`),
+  KEY `idx_api_keys_expires_at` (`expires_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='用户API密钥表';
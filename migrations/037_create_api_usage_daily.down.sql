@@ -0,0 +1,6 @@
+-- =============================================================
+-- 037_create_api_usage_daily.down.sql
+-- 回滚：删除API用量日汇总表
+-- =============================================================
+
+DROP TABLE IF EXISTS `api_usage_daily`;
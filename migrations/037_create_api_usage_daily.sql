@@ -0,0 +1,24 @@
+-- =============================================================
+-- 037_create_api_usage_daily.sql
+-- API用量日汇总
+-- 按 用户 + 日期 + API密钥 + 接口 汇总请求数、失败数和流量，由内存计数定期累加写入；
+-- api_key_id为0表示通过登录会话发起的请求，日期按UTC划分并以YYYY-MM-DD保存
+-- =============================================================
+
+CREATE TABLE `api_usage_daily` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '汇总记录ID',
+  `user_id` int unsigned NOT NULL COMMENT '用户ID',
+  `date` char(10) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '统计日期(YYYY-MM-DD，UTC)',
+  `api_key_id` bigint unsigned NOT NULL DEFAULT '0' COMMENT 'API密钥ID，0表示登录会话',
+  `endpoint` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '接口(方法+路由模板)',
+  `requests` bigint NOT NULL DEFAULT '0' COMMENT '请求数',
+  `errors` bigint NOT NULL DEFAULT '0' COMMENT '失败请求数(状态码>=400)',
+  `bytes_in` bigint NOT NULL DEFAULT '0' COMMENT '请求流量(字节)',
+  `bytes_out` bigint NOT NULL DEFAULT '0' COMMENT '响应流量(字节)',
+  `created_at` datetime(3) DEFAULT NULL COMMENT '创建时间',
+  `updated_at` datetime(3) DEFAULT NULL COMMENT '更新时间',
+  `version` bigint DEFAULT '1' COMMENT '乐观锁版本号',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_api_usage_daily` (`user_id`,`date`,`api_key_id`,`endpoint`),
+  KEY `idx_api_usage_daily_date` (`date`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='API用量日汇总表';
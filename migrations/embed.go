@@ -0,0 +1,12 @@
+// Package migrations 内嵌数据库迁移SQL文件，由 cmd/migrate 通过 internal/pkg/database/migrate 执行
+//
+// 文件命名：版本_名称.up.sql 和 版本_名称.down.sql；早期的迁移命名为 版本_名称.sql，基础架构(001-007)没有回滚文件。
+// 不能安全回滚的迁移在回滚文件中写 "-- irreversible: 原因"，回滚时迁移工具报错且不做任何修改
+package migrations
+
+import "embed"
+
+// FS 迁移文件
+//
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,86 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// User 用户信息
+type User struct {
+	ID          uint      `json:"id"`           // 用户ID
+	UUID        string    `json:"uuid"`         // 用户唯一标识
+	Username    string    `json:"username"`     // 用户名
+	Email       string    `json:"email"`        // 邮箱
+	DisplayName string    `json:"display_name"` // 显示名称
+	Avatar      string    `json:"avatar"`       // 头像地址
+	Status      string    `json:"status"`       // 账户状态
+	Role        string    `json:"role"`         // 角色
+	CreatedAt   time.Time `json:"created_at"`   // 注册时间
+}
+
+// LoginRequest 登录请求
+type LoginRequest struct {
+	Identifier       string `json:"identifier"`                  // 用户名、邮箱或手机号
+	Password         string `json:"password,omitempty"`          // 密码
+	LoginType        string `json:"login_type,omitempty"`        // 登录方式，为空时由服务端判断
+	RememberMe       bool   `json:"remember_me,omitempty"`       // 记住登录
+	VerificationCode string `json:"verification_code,omitempty"` // 验证码登录时的验证码
+}
+
+// Session 登录或刷新令牌的结果
+type Session struct {
+	AccessToken  string `json:"access_token"`  // 访问令牌
+	RefreshToken string `json:"refresh_token"` // 刷新令牌
+	TokenType    string `json:"token_type"`    // 令牌类型(Bearer)
+	ExpiresIn    int64  `json:"expires_in"`    // 访问令牌有效期(秒)
+	User         *User  `json:"user"`          // 当前用户
+}
+
+// Login 使用账号密码登录，成功后客户端保存令牌用于后续请求
+func (c *Client) Login(ctx context.Context, identifier, password string) (*Session, error) {
+	return c.LoginWith(ctx, &LoginRequest{Identifier: identifier, Password: password})
+}
+
+// LoginWith 使用完整的登录请求登录，如验证码登录
+func (c *Client) LoginWith(ctx context.Context, req *LoginRequest) (*Session, error) {
+	var session Session
+	if err := c.doJSON(ctx, http.MethodPost, "/api/v1/auth/login", req, &session); err != nil {
+		return nil, err
+	}
+	if session.AccessToken == "" {
+		return nil, fmt.Errorf("登录响应中缺少访问令牌")
+	}
+	c.SetTokens(session.AccessToken, session.RefreshToken)
+	return &session, nil
+}
+
+// Refresh 使用刷新令牌换取新的令牌对，旧刷新令牌随即失效
+func (c *Client) Refresh(ctx context.Context) (*Session, error) {
+	_, refreshToken := c.Tokens()
+	if refreshToken == "" {
+		return nil, fmt.Errorf("没有可用的刷新令牌")
+	}
+
+	var session Session
+	body := map[string]string{"refresh_token": refreshToken}
+	if err := c.doJSON(ctx, http.MethodPost, "/api/v1/auth/refresh", body, &session); err != nil {
+		return nil, err
+	}
+	c.SetTokens(session.AccessToken, session.RefreshToken)
+	return &session, nil
+}
+
+// Logout 吊销刷新令牌并清除客户端保存的令牌
+func (c *Client) Logout(ctx context.Context) error {
+	_, refreshToken := c.Tokens()
+	if refreshToken != "" {
+		body := map[string]string{"refresh_token": refreshToken}
+		if err := c.doJSON(ctx, http.MethodPost, "/api/v1/auth/logout", body, nil); err != nil {
+			return err
+		}
+	}
+	c.SetTokens("", "")
+	return nil
+}
@@ -0,0 +1,230 @@
+// Package client 云盘服务的Go客户端SDK
+//
+// 封装认证、文件(浏览/新建文件夹/重命名/移动/复制/回收站/下载)、分片上传和分享接口，
+// 解析服务端统一响应结构，业务错误以 *APIError 返回。
+// 供命令行工具、集成测试和第三方程序使用，无需手写HTTP请求。
+//
+// 使用示例：
+//
+//	c := client.NewClient("https://pan.example.com", nil)
+//	if _, err := c.Login(ctx, "alice", "secret"); err != nil {
+//		return err
+//	}
+//	file, err := c.UploadFile(ctx, "/tmp/report.pdf", nil)
+//	_, err = c.Download(ctx, file.ID, os.Stdout)
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// 服务端业务状态码
+const (
+	CodeSuccess      = 200 // 成功
+	CodeBadRequest   = 400 // 请求参数错误
+	CodeUnauthorized = 401 // 未认证
+	CodeForbidden    = 403 // 权限不足
+	CodeNotFound     = 404 // 资源不存在
+	CodeConflict     = 409 // 资源冲突(如同名文件)
+	CodeTooManyReqs  = 429 // 请求过于频繁
+)
+
+// envelope 服务端统一响应结构
+type envelope struct {
+	Code       int             `json:"code"`
+	Message    string          `json:"message"`
+	Data       json.RawMessage `json:"data"`
+	Pagination *Pagination     `json:"pagination"`
+	RequestID  string          `json:"request_id"`
+}
+
+// Pagination 列表接口的分页信息
+type Pagination struct {
+	CurrentPage int   `json:"current_page"` // 当前页码
+	PageSize    int   `json:"page_size"`    // 每页大小
+	TotalCount  int64 `json:"total_count"`  // 总记录数
+	TotalPages  int   `json:"total_pages"`  // 总页数
+	HasPrevious bool  `json:"has_previous"` // 是否有上一页
+	HasNext     bool  `json:"has_next"`     // 是否有下一页
+}
+
+// APIError 服务端返回的业务错误
+type APIError struct {
+	StatusCode int    // HTTP状态码
+	Code       int    // 业务状态码
+	Message    string // 错误消息
+	RequestID  string // 请求ID，反馈问题时提供
+}
+
+// Error 实现error接口
+func (e *APIError) Error() string {
+	if e.RequestID != "" {
+		return fmt.Sprintf("cloudpan: code=%d message=%s request_id=%s", e.Code, e.Message, e.RequestID)
+	}
+	return fmt.Sprintf("cloudpan: code=%d message=%s", e.Code, e.Message)
+}
+
+// IsCode 检查错误是否为指定业务状态码的服务端错误
+func IsCode(err error, code int) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Code == code
+}
+
+// Client 云盘服务客户端，可被多个goroutine并发使用
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+
+	mu           sync.RWMutex
+	accessToken  string
+	refreshToken string
+}
+
+// NewClient 创建客户端，httpClient为nil时使用http.DefaultClient
+func NewClient(baseURL string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: httpClient,
+	}
+}
+
+// SetTokens 设置访问令牌和刷新令牌，用于复用已保存的登录状态
+func (c *Client) SetTokens(accessToken, refreshToken string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.accessToken = accessToken
+	c.refreshToken = refreshToken
+}
+
+// Tokens 返回当前的访问令牌和刷新令牌
+func (c *Client) Tokens() (accessToken, refreshToken string) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.accessToken, c.refreshToken
+}
+
+// request 单次请求的参数
+type request struct {
+	method      string
+	path        string
+	body        io.Reader
+	contentType string
+	header      http.Header
+}
+
+// doJSON 发送JSON请求并将响应数据解析到out
+func (c *Client) doJSON(ctx context.Context, method, path string, body, out interface{}) error {
+	_, err := c.doJSONList(ctx, method, path, body, out)
+	return err
+}
+
+// doJSONList 发送JSON请求，同时返回列表接口的分页信息
+func (c *Client) doJSONList(ctx context.Context, method, path string, body, out interface{}) (*Pagination, error) {
+	req := &request{method: method, path: path}
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("序列化请求失败: %w", err)
+		}
+		req.body = bytes.NewReader(data)
+		req.contentType = "application/json"
+	}
+	return c.do(ctx, req, out)
+}
+
+// do 发送请求并解析统一响应结构，HTTP状态码或业务状态码非成功时返回 *APIError
+func (c *Client) do(ctx context.Context, req *request, out interface{}) (*Pagination, error) {
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应失败: %w", err)
+	}
+
+	var result envelope
+	if err := json.Unmarshal(data, &result); err != nil {
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return nil, &APIError{StatusCode: resp.StatusCode, Code: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+		}
+		return nil, fmt.Errorf("%s %s: 解析响应失败: %w", req.method, req.path, err)
+	}
+	if result.Code != CodeSuccess || resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, &APIError{
+			StatusCode: resp.StatusCode,
+			Code:       result.Code,
+			Message:    result.Message,
+			RequestID:  result.RequestID,
+		}
+	}
+
+	if out != nil && len(result.Data) > 0 && string(result.Data) != "null" {
+		if err := json.Unmarshal(result.Data, out); err != nil {
+			return nil, fmt.Errorf("%s %s: 解析响应数据失败: %w", req.method, req.path, err)
+		}
+	}
+	return result.Pagination, nil
+}
+
+// stream 发送请求并返回原始响应体，用于下载等非JSON响应
+//
+// 状态码不在 acceptStatus 中时解析错误响应并返回 *APIError，调用方负责关闭返回的响应体
+func (c *Client) stream(ctx context.Context, req *request, acceptStatus ...int) (*http.Response, error) {
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	for _, status := range acceptStatus {
+		if resp.StatusCode == status {
+			return resp, nil
+		}
+	}
+	defer resp.Body.Close()
+
+	apiErr := &APIError{StatusCode: resp.StatusCode, Code: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+	var result envelope
+	if data, err := io.ReadAll(resp.Body); err == nil && json.Unmarshal(data, &result) == nil && result.Code != 0 {
+		apiErr.Code, apiErr.Message, apiErr.RequestID = result.Code, result.Message, result.RequestID
+	}
+	return nil, apiErr
+}
+
+// send 构造并发送HTTP请求，已登录时携带访问令牌
+func (c *Client) send(ctx context.Context, req *request) (*http.Response, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, c.baseURL+req.path, req.body)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range req.header {
+		for _, value := range values {
+			httpReq.Header.Add(key, value)
+		}
+	}
+	if req.contentType != "" {
+		httpReq.Header.Set("Content-Type", req.contentType)
+	}
+	if token, _ := c.Tokens(); token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+token)
+	}
+	httpReq.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %w", req.method, req.path, err)
+	}
+	return resp, nil
+}
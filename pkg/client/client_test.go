@@ -0,0 +1,226 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// respond 按服务端统一响应结构写入响应
+func respond(w http.ResponseWriter, status, code int, message string, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"code": code, "message": message, "data": data, "request_id": "req-1",
+	})
+}
+
+// fakeUploadServer 模拟分片上传接口，记录收到的分片
+type fakeUploadServer struct {
+	mu       sync.Mutex
+	session  UploadSession
+	received map[int][]byte
+	puts     []int
+}
+
+func newFakeUploadServer(t *testing.T, upload *fakeUploadServer) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/files/uploads", func(w http.ResponseWriter, r *http.Request) {
+		var req UploadRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, []string{ChunkHashCRC32C, ChunkHashMD5}, req.ChunkHashAlgorithms)
+		assert.Equal(t, "5d41402abc4b2a76b9719d911017c592", req.Hash) // md5("hello")
+		respond(w, http.StatusOK, CodeSuccess, "ok", upload.state())
+	})
+	mux.HandleFunc("/api/v1/files/uploads/u1", func(w http.ResponseWriter, r *http.Request) {
+		respond(w, http.StatusOK, CodeSuccess, "ok", upload.state())
+	})
+	mux.HandleFunc("/api/v1/files/uploads/u1/chunks/", func(w http.ResponseWriter, r *http.Request) {
+		index, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/v1/files/uploads/u1/chunks/"))
+		data, _ := io.ReadAll(r.Body)
+		sum := crc32.New(crc32cTable)
+		sum.Write(data)
+		if r.Header.Get(ChunkHashHeader) != hex.EncodeToString(sum.Sum(nil)) {
+			respond(w, http.StatusBadRequest, CodeBadRequest, "分片哈希校验失败", nil)
+			return
+		}
+		upload.mu.Lock()
+		upload.received[index] = data
+		upload.puts = append(upload.puts, index)
+		upload.mu.Unlock()
+		respond(w, http.StatusOK, CodeSuccess, "ok", upload.state())
+	})
+	mux.HandleFunc("/api/v1/files/uploads/u1/merge", func(w http.ResponseWriter, r *http.Request) {
+		respond(w, http.StatusOK, CodeSuccess, "ok", File{ID: 9, Name: "hello.txt", Path: "/", Size: 5})
+	})
+	return httptest.NewServer(mux)
+}
+
+func (s *fakeUploadServer) state() UploadSession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session := s.session
+	session.UploadedChunks = []int{}
+	for index := range s.received {
+		session.UploadedChunks = append(session.UploadedChunks, index)
+	}
+	return session
+}
+
+func TestClient_Login(t *testing.T) {
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/auth/login":
+			respond(w, http.StatusOK, CodeSuccess, "登录成功", Session{AccessToken: "at", RefreshToken: "rt", User: &User{ID: 7}})
+		case "/api/v1/trash":
+			authorization = r.Header.Get("Authorization")
+			assert.Equal(t, "2", r.URL.Query().Get("page"))
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"code":200,"data":[{"id":1,"name":"a.txt"}],"pagination":{"current_page":2,"total_count":21,"has_next":false}}`))
+		}
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL+"/", nil)
+	session, err := c.Login(context.Background(), "alice", "secret")
+	require.NoError(t, err)
+	assert.Equal(t, uint(7), session.User.ID)
+
+	items, pagination, err := c.ListTrash(context.Background(), 2, 20)
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer at", authorization)
+	require.Len(t, items, 1)
+	assert.Equal(t, int64(21), pagination.TotalCount)
+}
+
+func TestClient_APIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		respond(w, http.StatusConflict, CodeConflict, "目标文件夹中已存在同名文件", nil)
+	}))
+	defer server.Close()
+
+	_, err := NewClient(server.URL, nil).RenameFile(context.Background(), 3, "b.txt")
+	require.Error(t, err)
+	assert.True(t, IsCode(err, CodeConflict))
+
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusConflict, apiErr.StatusCode)
+	assert.Equal(t, "req-1", apiErr.RequestID)
+}
+
+func TestClient_Upload(t *testing.T) {
+	newUpload := func() *fakeUploadServer {
+		return &fakeUploadServer{
+			session: UploadSession{
+				UploadID: "u1", Size: 5, ChunkSize: 2, TotalChunks: 3, ChunkHashAlgorithm: ChunkHashCRC32C,
+			},
+			received: make(map[int][]byte),
+		}
+	}
+
+	t.Run("new upload", func(t *testing.T) {
+		upload := newUpload()
+		server := newFakeUploadServer(t, upload)
+		defer server.Close()
+
+		var progress []int64
+		file, err := NewClient(server.URL, nil).Upload(context.Background(), bytes.NewReader([]byte("hello")), 5, &UploadOptions{
+			Name:       "hello.txt",
+			OnProgress: func(uploaded, _ int64) { progress = append(progress, uploaded) },
+		})
+		require.NoError(t, err)
+		assert.Equal(t, uint(9), file.ID)
+		assert.Equal(t, []int64{2, 4, 5}, progress)
+		assert.Equal(t, "he", string(upload.received[0]))
+		assert.Equal(t, "o", string(upload.received[2]))
+	})
+
+	t.Run("resume skips received chunks", func(t *testing.T) {
+		upload := newUpload()
+		upload.received[0] = []byte("he")
+		server := newFakeUploadServer(t, upload)
+		defer server.Close()
+
+		_, err := NewClient(server.URL, nil).Upload(context.Background(), bytes.NewReader([]byte("hello")), 5, &UploadOptions{ResumeID: "u1"})
+		require.NoError(t, err)
+		assert.Equal(t, []int{1, 2}, upload.puts)
+	})
+
+	t.Run("resume with different data", func(t *testing.T) {
+		upload := newUpload()
+		server := newFakeUploadServer(t, upload)
+		defer server.Close()
+
+		_, err := NewClient(server.URL, nil).Upload(context.Background(), bytes.NewReader([]byte("hi")), 2, &UploadOptions{ResumeID: "u1"})
+		assert.Error(t, err)
+		assert.Empty(t, upload.puts)
+	})
+}
+
+func TestChunkHash(t *testing.T) {
+	sum, err := chunkHash(ChunkHashMD5, []byte("hello"))
+	require.NoError(t, err)
+	assert.Equal(t, "5d41402abc4b2a76b9719d911017c592", sum)
+
+	sum, err = chunkHash(ChunkHashCRC32C, []byte("hello"))
+	require.NoError(t, err)
+	assert.Equal(t, "9a71bb4c", sum)
+
+	_, err = chunkHash("sha512", nil)
+	assert.Error(t, err)
+}
+
+func TestClient_Downloads(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/files/3/download":
+			if r.Header.Get("Range") == "bytes=2-" {
+				w.WriteHeader(http.StatusPartialContent)
+				_, _ = w.Write([]byte("llo"))
+				return
+			}
+			_, _ = w.Write([]byte("hello"))
+		case "/api/v1/public/shares/abc/download":
+			if r.Header.Get(SharePasswordHeader) != "pw" {
+				respond(w, http.StatusForbidden, CodeForbidden, "分享密码错误", nil)
+				return
+			}
+			_, _ = w.Write([]byte("shared"))
+		}
+	}))
+	defer server.Close()
+	c := NewClient(server.URL, nil)
+	ctx := context.Background()
+
+	var buf bytes.Buffer
+	n, err := c.Download(ctx, 3, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), n)
+
+	body, err := c.OpenDownload(ctx, 3, 2)
+	require.NoError(t, err)
+	rest, _ := io.ReadAll(body)
+	body.Close()
+	assert.Equal(t, "llo", string(rest))
+
+	buf.Reset()
+	_, err = c.DownloadShare(ctx, "abc", "wrong", &buf)
+	assert.True(t, IsCode(err, CodeForbidden))
+	_, err = c.DownloadShare(ctx, "abc", "pw", &buf)
+	require.NoError(t, err)
+	assert.Equal(t, "shared", buf.String())
+}
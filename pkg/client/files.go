@@ -0,0 +1,224 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// listAllPageSize 自动翻页时的每页数量(服务端上限)
+const listAllPageSize = 200
+
+// File 文件或文件夹
+type File struct {
+	ID           uint       `json:"id"`                      // 文件ID
+	UUID         string     `json:"uuid"`                    // 文件唯一标识符
+	UserID       uint       `json:"user_id"`                 // 所属用户ID
+	ParentID     *uint      `json:"parent_id,omitempty"`     // 父文件夹ID，为空表示根目录
+	Name         string     `json:"name"`                    // 文件名
+	Path         string     `json:"path"`                    // 所在文件夹路径
+	IsFolder     bool       `json:"is_folder"`               // 是否为文件夹
+	MimeType     *string    `json:"mime_type,omitempty"`     // 内容类型
+	Extension    *string    `json:"extension,omitempty"`     // 扩展名
+	Size         int64      `json:"size"`                    // 文件大小(字节)
+	Hash         *string    `json:"hash,omitempty"`          // 文件哈希
+	HashType     *string    `json:"hash_type,omitempty"`     // 哈希算法
+	StorageClass string     `json:"storage_class"`           // 存储类型
+	ArchivedAt   *time.Time `json:"archived_at,omitempty"`   // 归档时间
+	AccessLevel  string     `json:"access_level"`            // 访问级别
+	Status       string     `json:"status"`                  // 文件状态
+	Description  *string    `json:"description,omitempty"`   // 文件描述
+	ThumbnailURL *string    `json:"thumbnail_url,omitempty"` // 缩略图地址
+	CreatedAt    time.Time  `json:"created_at"`              // 创建时间
+	UpdatedAt    time.Time  `json:"updated_at"`              // 更新时间
+}
+
+// FullPath 返回文件的完整路径
+func (f *File) FullPath() string {
+	if f.Path == "" || f.Path == "/" {
+		return "/" + f.Name
+	}
+	return f.Path + "/" + f.Name
+}
+
+// TrashItem 回收站项目
+type TrashItem struct {
+	ID           uint      `json:"id"`             // 回收站项目ID
+	FileID       uint      `json:"file_id"`        // 文件ID
+	Name         string    `json:"name"`           // 文件名
+	OriginalPath string    `json:"original_path"`  // 删除前的完整路径
+	IsFolder     bool      `json:"is_folder"`      // 是否为文件夹
+	Size         int64     `json:"size"`           // 文件或文件夹内文件的总大小
+	TrashedAt    time.Time `json:"trashed_at"`     // 移入回收站的时间
+	AutoDeleteAt time.Time `json:"auto_delete_at"` // 自动彻底删除的时间
+}
+
+// RestoredFile 回收站恢复结果
+type RestoredFile struct {
+	FileID    uint   `json:"file_id"`   // 文件ID
+	Name      string `json:"name"`      // 恢复后的文件名
+	Path      string `json:"path"`      // 恢复后的完整路径
+	Relocated bool   `json:"relocated"` // 原文件夹不存在，已恢复到根目录
+	Renamed   bool   `json:"renamed"`   // 目标位置有同名文件，已自动重命名
+}
+
+// ListFiles 分页列出文件夹内容，parentID为nil表示根目录，page从1开始
+func (c *Client) ListFiles(ctx context.Context, parentID *uint, page, pageSize int) ([]File, *Pagination, error) {
+	query := url.Values{}
+	if parentID != nil {
+		query.Set("parent_id", strconv.FormatUint(uint64(*parentID), 10))
+	}
+	if page > 0 {
+		query.Set("page", strconv.Itoa(page))
+	}
+	if pageSize > 0 {
+		query.Set("page_size", strconv.Itoa(pageSize))
+	}
+	path := "/api/v1/files"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	var files []File
+	pagination, err := c.doJSONList(ctx, http.MethodGet, path, nil, &files)
+	if err != nil {
+		return nil, nil, err
+	}
+	return files, pagination, nil
+}
+
+// ListAllFiles 列出文件夹的全部内容，自动翻页
+func (c *Client) ListAllFiles(ctx context.Context, parentID *uint) ([]File, error) {
+	var all []File
+	for page := 1; ; page++ {
+		files, pagination, err := c.ListFiles(ctx, parentID, page, listAllPageSize)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, files...)
+		if pagination == nil || !pagination.HasNext || len(files) == 0 {
+			return all, nil
+		}
+	}
+}
+
+// CreateFolder 新建文件夹，parentID为nil表示根目录，同名时返回 CodeConflict 错误
+func (c *Client) CreateFolder(ctx context.Context, parentID *uint, name string) (*File, error) {
+	var folder File
+	body := map[string]interface{}{"name": name, "parent_id": parentID}
+	if err := c.doJSON(ctx, http.MethodPost, "/api/v1/files/folders", body, &folder); err != nil {
+		return nil, err
+	}
+	return &folder, nil
+}
+
+// RenameFile 重命名文件或文件夹，同一文件夹下重名时返回 CodeConflict 错误
+func (c *Client) RenameFile(ctx context.Context, fileID uint, name string) (*File, error) {
+	var file File
+	body := map[string]string{"name": name}
+	if err := c.doJSON(ctx, http.MethodPatch, filePath(fileID, ""), body, &file); err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+// MoveFile 将文件或文件夹移动到目标文件夹，parentID为nil表示根目录
+func (c *Client) MoveFile(ctx context.Context, fileID uint, parentID *uint) (*File, error) {
+	var file File
+	body := map[string]*uint{"parent_id": parentID}
+	if err := c.doJSON(ctx, http.MethodPost, filePath(fileID, "/move"), body, &file); err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+// CopyFile 将文件或文件夹复制到目标文件夹，parentID为nil表示根目录，重名时服务端自动追加序号
+func (c *Client) CopyFile(ctx context.Context, fileID uint, parentID *uint) (*File, error) {
+	var file File
+	body := map[string]*uint{"parent_id": parentID}
+	if err := c.doJSON(ctx, http.MethodPost, filePath(fileID, "/copy"), body, &file); err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+// TrashFile 将文件或文件夹移入回收站
+func (c *Client) TrashFile(ctx context.Context, fileID uint) (*TrashItem, error) {
+	var item TrashItem
+	if err := c.doJSON(ctx, http.MethodDelete, filePath(fileID, ""), nil, &item); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// ListTrash 分页获取回收站项目，page从1开始
+func (c *Client) ListTrash(ctx context.Context, page, pageSize int) ([]TrashItem, *Pagination, error) {
+	query := url.Values{}
+	if page > 0 {
+		query.Set("page", strconv.Itoa(page))
+	}
+	if pageSize > 0 {
+		query.Set("page_size", strconv.Itoa(pageSize))
+	}
+	path := "/api/v1/trash"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	var items []TrashItem
+	pagination, err := c.doJSONList(ctx, http.MethodGet, path, nil, &items)
+	if err != nil {
+		return nil, nil, err
+	}
+	return items, pagination, nil
+}
+
+// RestoreTrashItem 恢复回收站项目
+func (c *Client) RestoreTrashItem(ctx context.Context, itemID uint) (*RestoredFile, error) {
+	var restored RestoredFile
+	path := fmt.Sprintf("/api/v1/trash/%d/restore", itemID)
+	if err := c.doJSON(ctx, http.MethodPost, path, nil, &restored); err != nil {
+		return nil, err
+	}
+	return &restored, nil
+}
+
+// DeleteTrashItem 彻底删除回收站项目，不可恢复
+func (c *Client) DeleteTrashItem(ctx context.Context, itemID uint) error {
+	return c.doJSON(ctx, http.MethodDelete, fmt.Sprintf("/api/v1/trash/%d", itemID), nil, nil)
+}
+
+// Download 下载文件内容并写入w，返回写入的字节数
+func (c *Client) Download(ctx context.Context, fileID uint, w io.Writer) (int64, error) {
+	body, err := c.OpenDownload(ctx, fileID, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer body.Close()
+	return io.Copy(w, body)
+}
+
+// OpenDownload 打开文件下载流，offset大于0时从该字节处续传，调用方负责关闭
+func (c *Client) OpenDownload(ctx context.Context, fileID uint, offset int64) (io.ReadCloser, error) {
+	req := &request{method: http.MethodGet, path: filePath(fileID, "/download")}
+	accept := []int{http.StatusOK}
+	if offset > 0 {
+		req.header = http.Header{"Range": {fmt.Sprintf("bytes=%d-", offset)}}
+		accept = []int{http.StatusPartialContent}
+	}
+
+	resp, err := c.stream(ctx, req, accept...)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// filePath 拼接文件接口路径
+func filePath(fileID uint, suffix string) string {
+	return fmt.Sprintf("/api/v1/files/%d%s", fileID, suffix)
+}
@@ -0,0 +1,182 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// SharePasswordHeader 访问设置了密码的分享时携带密码的请求头
+const SharePasswordHeader = "X-Share-Password"
+
+// ShareAccess 分享访问信息
+type ShareAccess struct {
+	ShareID    uint       `json:"share_id"`             // 分享ID
+	ShareCode  string     `json:"share_code"`           // 分享码
+	FileID     uint       `json:"file_id"`              // 分享的文件ID
+	Permission string     `json:"permission"`           // 权限类型
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"` // 过期时间
+}
+
+// SharedFile 分享的文件
+type SharedFile struct {
+	ID        uint      `json:"id"`         // 文件ID
+	Name      string    `json:"name"`       // 文件名
+	Size      int64     `json:"size"`       // 文件大小
+	MimeType  string    `json:"mime_type"`  // 内容类型
+	IsFolder  bool      `json:"is_folder"`  // 是否为文件夹
+	UpdatedAt time.Time `json:"updated_at"` // 更新时间
+}
+
+// ShareInfo 分享链接解析结果
+type ShareInfo struct {
+	ShareAccess
+	Downloadable      bool       `json:"downloadable"`                 // 是否允许下载
+	RemainingAccess   *int       `json:"remaining_access,omitempty"`   // 剩余访问次数，不限制时为空
+	RemainingDownload *int       `json:"remaining_download,omitempty"` // 剩余下载次数，不限制时为空
+	File              SharedFile `json:"file"`                         // 分享的文件
+	DownloadURL       string     `json:"download_url,omitempty"`       // 下载地址
+}
+
+// TransferStatus 分享流量状态
+type TransferStatus struct {
+	ShareID     uint      `json:"share_id"`     // 分享ID
+	Limit       int64     `json:"limit"`        // 本周期流量上限(字节，0表示不限制)
+	Used        int64     `json:"used"`         // 本周期已消耗流量
+	Remaining   int64     `json:"remaining"`    // 剩余流量(不限制时为-1)
+	Exhausted   bool      `json:"exhausted"`    // 流量是否已用尽
+	PeriodStart time.Time `json:"period_start"` // 统计周期开始时间
+	ResetsAt    time.Time `json:"resets_at"`    // 流量恢复时间
+}
+
+// SharePrivacy 分享隐私设置
+type SharePrivacy struct {
+	StripImageLocation bool   `json:"strip_image_location"` // 下载图片时是否去除位置信息
+	Source             string `json:"source"`               // 生效设置的来源(share/user/system)
+}
+
+// CreateShareRequest 创建分享请求
+type CreateShareRequest struct {
+	FileID      uint   `json:"file_id"`                // 分享的文件ID
+	Permission  string `json:"permission,omitempty"`   // 权限类型(view/download)，默认download
+	Password    string `json:"password,omitempty"`     // 分享密码，为空表示不设置
+	ExpireDays  int    `json:"expire_days,omitempty"`  // 有效天数，0表示永久有效
+	MaxAccess   *int   `json:"max_access,omitempty"`   // 最大访问次数，为空表示不限制
+	MaxDownload *int   `json:"max_download,omitempty"` // 最大下载次数，为空表示不限制
+	Template    string `json:"template,omitempty"`     // 应用的分享模板名称，为空使用默认模板
+	NoTemplate  bool   `json:"no_template,omitempty"`  // 不应用默认模板
+}
+
+// Share 分享
+type Share struct {
+	ID            uint       `json:"id"`                     // 分享ID
+	FileID        uint       `json:"file_id"`                // 分享的文件ID
+	ShareCode     string     `json:"share_code"`             // 分享码
+	ShareURL      string     `json:"share_url"`              // 分享链接(相对服务地址)
+	Permission    string     `json:"permission"`             // 权限类型
+	HasPassword   bool       `json:"has_password"`           // 是否设置密码
+	MaxAccess     *int       `json:"max_access,omitempty"`   // 最大访问次数
+	AccessCount   int        `json:"access_count"`           // 已访问次数
+	MaxDownload   *int       `json:"max_download,omitempty"` // 最大下载次数
+	DownloadCount int        `json:"download_count"`         // 已下载次数
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`   // 过期时间
+	Status        string     `json:"status"`                 // 分享状态
+	CreatedAt     time.Time  `json:"created_at"`             // 创建时间
+}
+
+// CreateShare 为自己的文件或文件夹创建分享链接
+func (c *Client) CreateShare(ctx context.Context, req *CreateShareRequest) (*Share, error) {
+	var share Share
+	if err := c.doJSON(ctx, http.MethodPost, "/api/v1/shares", req, &share); err != nil {
+		return nil, err
+	}
+	return &share, nil
+}
+
+// ShareLink 返回分享的完整链接
+func (c *Client) ShareLink(share *Share) string {
+	return c.baseURL + share.ShareURL
+}
+
+// ResolveShare 打开分享链接，每次成功打开占用一次访问次数；未设置密码时password为空
+func (c *Client) ResolveShare(ctx context.Context, code, password string) (*ShareInfo, error) {
+	var info ShareInfo
+	req := &request{method: http.MethodGet, path: sharePath(code, ""), header: sharePasswordHeader(password)}
+	if _, err := c.do(ctx, req, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// VerifySharePassword 验证分享密码
+func (c *Client) VerifySharePassword(ctx context.Context, code, password string) (*ShareAccess, error) {
+	var access ShareAccess
+	body := map[string]string{"password": password}
+	if err := c.doJSON(ctx, http.MethodPost, sharePath(code, "/verify"), body, &access); err != nil {
+		return nil, err
+	}
+	return &access, nil
+}
+
+// GetShareTransfer 查询分享本月下载流量状态
+func (c *Client) GetShareTransfer(ctx context.Context, code string) (*TransferStatus, error) {
+	var status TransferStatus
+	if err := c.doJSON(ctx, http.MethodGet, sharePath(code, "/transfer"), nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// DownloadShare 通过分享链接下载文件并写入w，返回写入的字节数
+func (c *Client) DownloadShare(ctx context.Context, code, password string, w io.Writer) (int64, error) {
+	req := &request{method: http.MethodGet, path: sharePath(code, "/download"), header: sharePasswordHeader(password)}
+	resp, err := c.stream(ctx, req, http.StatusOK)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	return io.Copy(w, resp.Body)
+}
+
+// SetSharePassword 设置分享密码，password为空表示取消密码
+func (c *Client) SetSharePassword(ctx context.Context, shareID uint, password string) error {
+	body := map[string]string{"password": password}
+	return c.doJSON(ctx, http.MethodPut, fmt.Sprintf("/api/v1/shares/%d/password", shareID), body, nil)
+}
+
+// GetSharePrivacy 查询当前用户分享的默认隐私设置
+func (c *Client) GetSharePrivacy(ctx context.Context) (*SharePrivacy, error) {
+	var privacy SharePrivacy
+	if err := c.doJSON(ctx, http.MethodGet, "/api/v1/shares/privacy", nil, &privacy); err != nil {
+		return nil, err
+	}
+	return &privacy, nil
+}
+
+// SetSharePrivacy 设置当前用户分享的默认隐私设置，stripImageLocation为nil表示恢复系统默认
+func (c *Client) SetSharePrivacy(ctx context.Context, stripImageLocation *bool) error {
+	body := map[string]*bool{"strip_image_location": stripImageLocation}
+	return c.doJSON(ctx, http.MethodPut, "/api/v1/shares/privacy", body, nil)
+}
+
+// SetShareItemPrivacy 设置单个分享的隐私设置，stripImageLocation为nil表示沿用用户默认
+func (c *Client) SetShareItemPrivacy(ctx context.Context, shareID uint, stripImageLocation *bool) error {
+	body := map[string]*bool{"strip_image_location": stripImageLocation}
+	return c.doJSON(ctx, http.MethodPut, fmt.Sprintf("/api/v1/shares/%d/privacy", shareID), body, nil)
+}
+
+// sharePath 拼接公开分享接口路径
+func sharePath(code, suffix string) string {
+	return "/api/v1/public/shares/" + url.PathEscape(code) + suffix
+}
+
+// sharePasswordHeader 构造分享密码请求头，密码为空时不携带
+func sharePasswordHeader(password string) http.Header {
+	if password == "" {
+		return nil
+	}
+	return http.Header{SharePasswordHeader: {password}}
+}
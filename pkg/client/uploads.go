@@ -0,0 +1,260 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5" // #nosec G501 - 服务端分片上传协议使用MD5校验完整性，非安全用途
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ChunkHashHeader 分片哈希请求头
+const ChunkHashHeader = "X-Chunk-Hash"
+
+// 分片校验算法
+const (
+	ChunkHashCRC32C = "crc32c"
+	ChunkHashMD5    = "md5"
+)
+
+// crc32cTable Castagnoli多项式表，与服务端一致
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+// UploadRequest 创建分片上传任务请求
+type UploadRequest struct {
+	Name                string   `json:"name"`                            // 文件名
+	Size                int64    `json:"size"`                            // 文件大小(字节)
+	Hash                string   `json:"hash"`                            // 文件整体哈希(十六进制)
+	HashType            string   `json:"hash_type,omitempty"`             // 文件哈希算法，默认md5
+	MimeType            string   `json:"mime_type,omitempty"`             // 内容类型
+	ParentID            *uint    `json:"parent_id"`                       // 目标文件夹ID，为空表示根目录
+	ChunkHashAlgorithms []string `json:"chunk_hash_algorithms,omitempty"` // 客户端支持的分片校验算法
+}
+
+// UploadSession 分片上传任务状态
+type UploadSession struct {
+	UploadID           string    `json:"upload_id"`            // 上传任务ID
+	FileID             uint      `json:"file_id"`              // 文件ID
+	Name               string    `json:"name"`                 // 规范化后的文件名
+	Size               int64     `json:"size"`                 // 文件总大小
+	ChunkSize          int64     `json:"chunk_size"`           // 分片大小
+	TotalChunks        int       `json:"total_chunks"`         // 总分片数
+	ChunkHashAlgorithm string    `json:"chunk_hash_algorithm"` // 协商的分片校验算法
+	UploadedChunks     []int     `json:"uploaded_chunks"`      // 已接收的分片索引
+	ExpiresAt          time.Time `json:"expires_at"`           // 上传任务过期时间
+}
+
+// IsComplete 检查全部分片是否已接收
+func (s *UploadSession) IsComplete() bool {
+	return len(s.UploadedChunks) == s.TotalChunks
+}
+
+// UploadOptions 上传选项
+type UploadOptions struct {
+	ParentID *uint  // 目标文件夹ID，为空表示根目录
+	Name     string // 文件名，为空时使用本地文件名
+	MimeType string // 内容类型，为空时按扩展名推断
+	ResumeID string // 续传的上传任务ID，为空时创建新任务
+
+	// OnSession 上传任务创建或读取后回调，可保存UploadID用于中断后续传
+	OnSession func(session *UploadSession)
+	// OnProgress 每个分片上传成功后回调，参数为已上传字节数和总字节数
+	OnProgress func(uploaded, total int64)
+}
+
+// InitiateUpload 创建分片上传任务
+func (c *Client) InitiateUpload(ctx context.Context, req *UploadRequest) (*UploadSession, error) {
+	var session UploadSession
+	if err := c.doJSON(ctx, http.MethodPost, "/api/v1/files/uploads", req, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// GetUpload 查询上传任务状态，用于续传前确认已接收的分片
+func (c *Client) GetUpload(ctx context.Context, uploadID string) (*UploadSession, error) {
+	var session UploadSession
+	if err := c.doJSON(ctx, http.MethodGet, uploadPath(uploadID, ""), nil, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// UploadChunk 上传单个分片，hash为协商算法计算的分片哈希(十六进制)
+func (c *Client) UploadChunk(ctx context.Context, uploadID string, index int, data []byte, hash string) (*UploadSession, error) {
+	var session UploadSession
+	req := &request{
+		method:      http.MethodPut,
+		path:        uploadPath(uploadID, fmt.Sprintf("/chunks/%d", index)),
+		body:        bytes.NewReader(data),
+		contentType: "application/octet-stream",
+		header:      http.Header{ChunkHashHeader: {hash}},
+	}
+	if _, err := c.do(ctx, req, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// MergeUpload 合并全部分片，返回合并完成的文件；可安全重试
+func (c *Client) MergeUpload(ctx context.Context, uploadID string) (*File, error) {
+	var file File
+	if err := c.doJSON(ctx, http.MethodPost, uploadPath(uploadID, "/merge"), nil, &file); err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+// UploadFile 以分片方式上传本地文件
+func (c *Client) UploadFile(ctx context.Context, localPath string, options *UploadOptions) (*File, error) {
+	f, err := os.Open(localPath) // #nosec G304 - 由调用方指定要上传的本地文件
+	if err != nil {
+		return nil, fmt.Errorf("打开文件失败: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("读取文件信息失败: %w", err)
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("不能上传文件夹: %s", localPath)
+	}
+
+	opts := UploadOptions{}
+	if options != nil {
+		opts = *options
+	}
+	if opts.Name == "" {
+		opts.Name = filepath.Base(localPath)
+	}
+	return c.Upload(ctx, f, info.Size(), &opts)
+}
+
+// Upload 以分片方式上传数据
+//
+// 先计算整体MD5并创建任务(或按ResumeID读取已有任务)，跳过服务端已接收的分片，
+// 逐片计算协商算法的哈希后上传，最后合并。中断后使用同一ResumeID重新调用即可续传
+func (c *Client) Upload(ctx context.Context, r io.ReaderAt, size int64, options *UploadOptions) (*File, error) {
+	opts := UploadOptions{}
+	if options != nil {
+		opts = *options
+	}
+
+	var session *UploadSession
+	var err error
+	if opts.ResumeID != "" {
+		session, err = c.GetUpload(ctx, opts.ResumeID)
+	} else {
+		session, err = c.initiate(ctx, r, size, &opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if session.Size != size {
+		return nil, fmt.Errorf("上传任务的文件大小(%d)与本地数据(%d)不一致", session.Size, size)
+	}
+	if opts.OnSession != nil {
+		opts.OnSession(session)
+	}
+
+	received := make(map[int]bool, len(session.UploadedChunks))
+	var uploaded int64
+	for _, index := range session.UploadedChunks {
+		received[index] = true
+		uploaded += chunkLength(session, index)
+	}
+
+	buffer := make([]byte, session.ChunkSize)
+	for index := 0; index < session.TotalChunks; index++ {
+		if received[index] {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		chunk := buffer[:chunkLength(session, index)]
+		if _, err := r.ReadAt(chunk, int64(index)*session.ChunkSize); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("读取分片%d失败: %w", index, err)
+		}
+		sum, err := chunkHash(session.ChunkHashAlgorithm, chunk)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := c.UploadChunk(ctx, session.UploadID, index, chunk, sum); err != nil {
+			return nil, fmt.Errorf("上传分片%d失败: %w", index, err)
+		}
+
+		uploaded += int64(len(chunk))
+		if opts.OnProgress != nil {
+			opts.OnProgress(uploaded, size)
+		}
+	}
+
+	return c.MergeUpload(ctx, session.UploadID)
+}
+
+// initiate 计算整体MD5并创建上传任务
+func (c *Client) initiate(ctx context.Context, r io.ReaderAt, size int64, opts *UploadOptions) (*UploadSession, error) {
+	if opts.Name == "" {
+		return nil, fmt.Errorf("文件名不能为空")
+	}
+
+	hasher := md5.New() // #nosec G401 - 服务端协议要求
+	if _, err := io.Copy(hasher, io.NewSectionReader(r, 0, size)); err != nil {
+		return nil, fmt.Errorf("计算文件哈希失败: %w", err)
+	}
+
+	mimeType := opts.MimeType
+	if mimeType == "" {
+		mimeType = mime.TypeByExtension(filepath.Ext(opts.Name))
+	}
+	return c.InitiateUpload(ctx, &UploadRequest{
+		Name:                opts.Name,
+		Size:                size,
+		Hash:                hex.EncodeToString(hasher.Sum(nil)),
+		HashType:            "md5",
+		MimeType:            mimeType,
+		ParentID:            opts.ParentID,
+		ChunkHashAlgorithms: []string{ChunkHashCRC32C, ChunkHashMD5},
+	})
+}
+
+// chunkLength 计算分片的实际长度，最后一片可能不足分片大小
+func chunkLength(session *UploadSession, index int) int64 {
+	offset := int64(index) * session.ChunkSize
+	if remaining := session.Size - offset; remaining < session.ChunkSize {
+		return remaining
+	}
+	return session.ChunkSize
+}
+
+// chunkHash 按协商的算法计算分片哈希
+func chunkHash(algorithm string, data []byte) (string, error) {
+	var hasher hash.Hash
+	switch algorithm {
+	case ChunkHashCRC32C:
+		hasher = crc32.New(crc32cTable)
+	case ChunkHashMD5, "":
+		hasher = md5.New() // #nosec G401 - 仅用于分片完整性校验
+	default:
+		return "", fmt.Errorf("不支持的分片校验算法: %s", algorithm)
+	}
+	hasher.Write(data)
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// uploadPath 拼接上传任务接口路径
+func uploadPath(uploadID, suffix string) string {
+	return "/api/v1/files/uploads/" + url.PathEscape(uploadID) + suffix
+}
@@ -0,0 +1,44 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"cloudpan/internal/pkg/cache"
+)
+
+// TestInvalidationBus_Redis 配置Redis时缓存失效总线使用Redis发布/订阅，消息送达其他实例
+func TestInvalidationBus_Redis(t *testing.T) {
+	require.NotNil(t, cache.RedisClient, "setupApp 按服务启动流程连接Redis")
+	transport := cache.NewDefaultInvalidationTransport()
+	require.IsType(t, &cache.RedisInvalidationTransport{}, transport)
+
+	// 两个总线相当于两个实例
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	publisher := cache.NewInvalidationBus(transport, nil)
+	receiver := cache.NewInvalidationBus(cache.NewDefaultInvalidationTransport(), nil)
+	require.True(t, publisher.CrossInstance())
+
+	var received atomic.Int32
+	receiver.Subscribe(cache.InvalidationReferenceData, func(_ context.Context, msg *cache.Invalidation) {
+		if msg.Origin == publisher.InstanceID() {
+			received.Add(1)
+		}
+	})
+	go func() { _ = publisher.Run(ctx) }()
+	go func() { _ = receiver.Run(ctx) }()
+
+	// 订阅建立前发布的消息会丢失，重复发布直到收到
+	assert.Eventually(t, func() bool {
+		require.NoError(t, publisher.Publish(ctx, cache.InvalidationReferenceData, "plans"))
+		return received.Load() > 0
+	}, 5*time.Second, 100*time.Millisecond)
+}
@@ -0,0 +1,84 @@
+//go:build integration
+
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"cloudpan/internal/pkg/utils"
+)
+
+// apiResponse 统一响应格式，data延迟解析到调用方的结构
+type apiResponse struct {
+	Code    utils.ResponseCode `json:"code"`
+	Message string             `json:"message"`
+	Data    json.RawMessage    `json:"data"`
+}
+
+// apiClient 携带访问令牌调用测试服务的HTTP接口，令牌为空时匿名访问
+type apiClient struct {
+	token string
+}
+
+// request 发送请求并返回响应，调用方负责关闭响应体
+func (c *apiClient) request(t *testing.T, method, path string, body io.Reader, headers map[string]string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, server.URL+path, body)
+	require.NoError(t, err)
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := server.Client().Do(req)
+	require.NoError(t, err)
+	return resp
+}
+
+// call 发送JSON请求，要求返回成功并把data解析到out
+func (c *apiClient) call(t *testing.T, method, path string, payload, out interface{}) {
+	t.Helper()
+	var body io.Reader
+	headers := map[string]string{}
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		require.NoError(t, err)
+		body = bytes.NewReader(data)
+		headers["Content-Type"] = "application/json"
+	}
+	c.decode(t, c.request(t, method, path, body, headers), out)
+}
+
+// decode 读取统一格式的响应，要求返回成功并把data解析到out
+func (c *apiClient) decode(t *testing.T, resp *http.Response, out interface{}) {
+	t.Helper()
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	var result apiResponse
+	require.NoError(t, json.Unmarshal(raw, &result), string(raw))
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(raw))
+	require.Equal(t, utils.CodeSuccess, result.Code, result.Message)
+	if out != nil {
+		require.NoError(t, json.Unmarshal(result.Data, out), string(result.Data))
+	}
+}
+
+// download 下载文件内容，要求返回200
+func (c *apiClient) download(t *testing.T, path string) []byte {
+	t.Helper()
+	resp := c.request(t, http.MethodGet, path, nil, nil)
+	defer resp.Body.Close()
+	content, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(content))
+	return content
+}
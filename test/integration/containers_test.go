@@ -0,0 +1,207 @@
+//go:build integration
+
+package integration
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+)
+
+// 依赖服务镜像，版本与部署环境保持一致
+const (
+	mysqlImage = "mysql"
+	mysqlTag   = "8.4"
+	redisImage = "redis"
+	redisTag   = "7.2-alpine"
+	minioImage = "minio/minio"
+	minioTag   = "RELEASE.2024-10-13T13-34-11Z"
+)
+
+// 依赖服务的测试账号
+const (
+	mysqlPassword  = "cloudpan_it"
+	mysqlDatabase  = "cloudpan_it"
+	minioAccessKey = "cloudpan-it"
+	minioSecretKey = "cloudpan-it-secret"
+	minioBucket    = "cloudpan-it"
+)
+
+// containerExpiry 容器最长存活时间(秒)，测试进程异常退出时由Docker回收
+const containerExpiry = 600
+
+// endpoint 依赖服务在宿主机上的地址
+type endpoint struct {
+	Host string
+	Port int
+}
+
+// String 返回 host:port
+func (e endpoint) String() string {
+	return net.JoinHostPort(e.Host, strconv.Itoa(e.Port))
+}
+
+// services 测试期间运行的依赖服务
+type services struct {
+	pool      *dockertest.Pool
+	resources []*dockertest.Resource
+
+	MySQL endpoint
+	Redis endpoint
+	MinIO endpoint
+}
+
+// startServices 启动MySQL、Redis和MinIO容器并等待服务可用，创建测试存储桶
+//
+// Docker地址从DOCKER_HOST读取；任一服务启动失败时清理已启动的容器
+func startServices() (*services, error) {
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		return nil, fmt.Errorf("连接Docker失败: %w", err)
+	}
+	if err := pool.Client.Ping(); err != nil {
+		return nil, fmt.Errorf("连接Docker失败: %w", err)
+	}
+	pool.MaxWait = 3 * time.Minute
+
+	s := &services{pool: pool}
+	steps := []func() error{s.startMySQL, s.startRedis, s.startMinIO}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			s.Close()
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Close 删除全部测试容器
+func (s *services) Close() {
+	for _, resource := range s.resources {
+		_ = s.pool.Purge(resource)
+	}
+	s.resources = nil
+}
+
+// run 启动容器并返回指定端口在宿主机上的地址
+func (s *services) run(options *dockertest.RunOptions, port string) (*dockertest.Resource, endpoint, error) {
+	resource, err := s.pool.RunWithOptions(options, func(config *docker.HostConfig) {
+		config.AutoRemove = true
+		config.RestartPolicy = docker.RestartPolicy{Name: "no"}
+	})
+	if err != nil {
+		return nil, endpoint{}, fmt.Errorf("启动%s容器失败: %w", options.Repository, err)
+	}
+	s.resources = append(s.resources, resource)
+	_ = resource.Expire(containerExpiry)
+
+	host, portText, err := net.SplitHostPort(resource.GetHostPort(port))
+	if err != nil {
+		return nil, endpoint{}, fmt.Errorf("获取%s端口失败: %w", options.Repository, err)
+	}
+	hostPort, err := strconv.Atoi(portText)
+	if err != nil {
+		return nil, endpoint{}, fmt.Errorf("获取%s端口失败: %w", options.Repository, err)
+	}
+	return resource, endpoint{Host: host, Port: hostPort}, nil
+}
+
+// startMySQL 启动MySQL，等待可以连接测试数据库
+func (s *services) startMySQL() error {
+	_, addr, err := s.run(&dockertest.RunOptions{
+		Repository: mysqlImage,
+		Tag:        mysqlTag,
+		Env: []string{
+			"MYSQL_ROOT_PASSWORD=" + mysqlPassword,
+			"MYSQL_DATABASE=" + mysqlDatabase,
+		},
+		Cmd: []string{"--character-set-server=utf8mb4", "--collation-server=utf8mb4_unicode_ci"},
+	}, "3306/tcp")
+	if err != nil {
+		return err
+	}
+	s.MySQL = addr
+
+	dsn := fmt.Sprintf("root:%s@tcp(%s)/%s?parseTime=true", mysqlPassword, addr, mysqlDatabase)
+	return s.pool.Retry(func() error {
+		db, err := sql.Open("mysql", dsn)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		return db.Ping()
+	})
+}
+
+// startRedis 启动Redis，等待PING成功
+func (s *services) startRedis() error {
+	_, addr, err := s.run(&dockertest.RunOptions{Repository: redisImage, Tag: redisTag}, "6379/tcp")
+	if err != nil {
+		return err
+	}
+	s.Redis = addr
+
+	return s.pool.Retry(func() error {
+		client := redis.NewClient(&redis.Options{Addr: addr.String()})
+		defer client.Close()
+		return client.Ping(context.Background()).Err()
+	})
+}
+
+// startMinIO 启动MinIO，等待健康检查通过后用容器内的mc创建测试存储桶
+func (s *services) startMinIO() error {
+	resource, addr, err := s.run(&dockertest.RunOptions{
+		Repository: minioImage,
+		Tag:        minioTag,
+		Env: []string{
+			"MINIO_ROOT_USER=" + minioAccessKey,
+			"MINIO_ROOT_PASSWORD=" + minioSecretKey,
+		},
+		Cmd: []string{"server", "/data"},
+	}, "9000/tcp")
+	if err != nil {
+		return err
+	}
+	s.MinIO = addr
+
+	healthURL := fmt.Sprintf("http://%s/minio/health/live", addr)
+	if err := s.pool.Retry(func() error {
+		resp, err := http.Get(healthURL) // #nosec G107 - 测试容器地址
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("MinIO未就绪: %d", resp.StatusCode)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	commands := [][]string{
+		{"mc", "alias", "set", "local", "http://127.0.0.1:9000", minioAccessKey, minioSecretKey},
+		{"mc", "mb", "--ignore-existing", "local/" + minioBucket},
+	}
+	for _, command := range commands {
+		var output bytes.Buffer
+		code, err := resource.Exec(command, dockertest.ExecOptions{StdOut: &output, StdErr: &output})
+		if err != nil {
+			return fmt.Errorf("创建MinIO存储桶失败: %w", err)
+		}
+		if code != 0 {
+			return fmt.Errorf("创建MinIO存储桶失败: %s", output.String())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+//go:build integration
+
+package integration
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5" // #nosec G501 - 与客户端约定的文件校验算法
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"cloudpan/internal/api/handlers"
+	"cloudpan/internal/pkg/config"
+	"cloudpan/internal/pkg/database"
+	"cloudpan/internal/pkg/utils"
+	"cloudpan/internal/repository/models"
+	userrepo "cloudpan/internal/repository/user"
+	filesvc "cloudpan/internal/service/file"
+	"cloudpan/internal/service/user"
+)
+
+// registerUser 注册用户并签发访问令牌
+//
+// 注册接口尚未实现，直接通过用户服务创建账号，之后的操作都通过HTTP接口完成
+func registerUser(t *testing.T, name string) (*models.User, *apiClient) {
+	t.Helper()
+	ctx := context.Background()
+	passwordHash, err := utils.HashPassword("Integration#2024")
+	require.NoError(t, err)
+
+	account := &models.User{
+		UUID:          uuid.NewString(),
+		Email:         name + "@example.com",
+		Username:      name,
+		PasswordHash:  passwordHash,
+		Status:        "active",
+		EmailVerified: true,
+	}
+	db := database.GetDB()
+	require.NoError(t, user.NewUserService(userrepo.NewUserRepository(db), nil, db).CreateUser(ctx, account))
+
+	jwtManager, err := utils.NewDefaultJWTManager(config.AppConfig.JWT.Secret)
+	require.NoError(t, err)
+	token, err := jwtManager.GenerateAccessToken(uint64(account.ID), account.Username, account.Email, "user")
+	require.NoError(t, err)
+	return account, &apiClient{token: token}
+}
+
+// uploadInChunks 按服务端的分片规划上传文件并合并，返回合并后的文件
+func uploadInChunks(t *testing.T, client *apiClient, name string, content []byte) *models.File {
+	t.Helper()
+	digest := md5.Sum(content) // #nosec G401 - 与客户端约定的文件校验算法
+	var session filesvc.ChunkedUploadSession
+	client.call(t, http.MethodPost, "/api/v1/files/uploads", filesvc.ChunkedUploadRequest{
+		Name:     name,
+		Size:     int64(len(content)),
+		Hash:     hex.EncodeToString(digest[:]),
+		HashType: "md5",
+		MimeType: "application/octet-stream",
+	}, &session)
+	require.Greater(t, session.TotalChunks, 1, "测试文件应分为多个分片")
+
+	for index := 0; index < session.TotalChunks; index++ {
+		start := int64(index) * session.ChunkSize
+		end := min(start+session.ChunkSize, int64(len(content)))
+		chunk := content[start:end]
+
+		hasher, err := filesvc.NewChunkHasher(session.ChunkHashAlgorithm)
+		require.NoError(t, err)
+		hasher.Write(chunk)
+		path := fmt.Sprintf("/api/v1/files/uploads/%s/chunks/%d", session.UploadID, index)
+		resp := client.request(t, http.MethodPut, path, bytes.NewReader(chunk), map[string]string{
+			"Content-Type":           "application/octet-stream",
+			handlers.ChunkHashHeader: hex.EncodeToString(hasher.Sum(nil)),
+		})
+		client.decode(t, resp, nil)
+	}
+
+	var progress filesvc.ChunkedUploadSession
+	client.call(t, http.MethodGet, "/api/v1/files/uploads/"+session.UploadID, nil, &progress)
+	require.True(t, progress.IsComplete())
+
+	var merged models.File
+	client.call(t, http.MethodPost, "/api/v1/files/uploads/"+session.UploadID+"/merge", nil, &merged)
+	return &merged
+}
+
+// TestEndToEnd_RegisterUploadShareDownload 注册 -> 分片上传 -> 创建分享 -> 匿名下载
+func TestEndToEnd_RegisterUploadShareDownload(t *testing.T) {
+	account, client := registerUser(t, "it_"+uuid.NewString()[:8])
+
+	// 约2.5个分片，最后一个分片小于分片大小
+	content := make([]byte, 160*1024)
+	_, err := rand.Read(content)
+	require.NoError(t, err)
+
+	file := uploadInChunks(t, client, "report.bin", content)
+	require.NotZero(t, file.ID)
+	assert.Equal(t, "report.bin", file.Name)
+	assert.Equal(t, int64(len(content)), file.Size)
+	assert.Equal(t, "active", file.Status)
+
+	// 合并后的对象写入MinIO，所有者可以下载
+	assert.Equal(t, content, client.download(t, "/api/v1/files/"+strconv.FormatUint(uint64(file.ID), 10)+"/download"))
+
+	// 上传完成后存储用量计入MySQL中的用户记录
+	stored, err := userrepo.NewUserRepository(database.GetDB()).GetByID(context.Background(), account.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(content)), stored.StorageUsed)
+
+	var share models.FileShare
+	client.call(t, http.MethodPost, "/api/v1/shares", map[string]interface{}{
+		"file_id":    file.ID,
+		"permission": "download",
+	}, &share)
+	require.NotEmpty(t, share.ShareCode)
+
+	// 未登录的访问者打开分享并下载
+	visitor := &apiClient{}
+	var info struct {
+		Downloadable bool `json:"downloadable"`
+		File         struct {
+			Name string `json:"name"`
+			Size int64  `json:"size"`
+		} `json:"file"`
+	}
+	visitor.call(t, http.MethodGet, "/api/v1/public/shares/"+share.ShareCode, nil, &info)
+	assert.True(t, info.Downloadable)
+	assert.Equal(t, "report.bin", info.File.Name)
+	assert.Equal(t, int64(len(content)), info.File.Size)
+
+	assert.Equal(t, content, visitor.download(t, "/api/v1/public/shares/"+share.ShareCode+"/download"))
+}
+
+// TestEndToEnd_ChunkHashMismatch 分片哈希不匹配时拒绝分片，上传任务不记录该分片
+func TestEndToEnd_ChunkHashMismatch(t *testing.T) {
+	_, client := registerUser(t, "it_"+uuid.NewString()[:8])
+
+	content := bytes.Repeat([]byte("cloudpan"), 16*1024)
+	digest := md5.Sum(content) // #nosec G401 - 与客户端约定的文件校验算法
+	var session filesvc.ChunkedUploadSession
+	client.call(t, http.MethodPost, "/api/v1/files/uploads", filesvc.ChunkedUploadRequest{
+		Name: "mismatch.bin",
+		Size: int64(len(content)),
+		Hash: hex.EncodeToString(digest[:]),
+	}, &session)
+
+	resp := client.request(t, http.MethodPut, "/api/v1/files/uploads/"+session.UploadID+"/chunks/0",
+		bytes.NewReader(content[:session.ChunkSize]), map[string]string{handlers.ChunkHashHeader: "00000000"})
+	resp.Body.Close()
+	assert.NotEqual(t, http.StatusOK, resp.StatusCode)
+
+	var progress filesvc.ChunkedUploadSession
+	client.call(t, http.MethodGet, "/api/v1/files/uploads/"+session.UploadID, nil, &progress)
+	assert.Empty(t, progress.UploadedChunks)
+}
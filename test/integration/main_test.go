@@ -0,0 +1,97 @@
+//go:build integration
+
+// Package integration 端到端集成测试
+//
+// 测试通过dockertest启动真实的MySQL、Redis和MinIO，执行数据库迁移后在进程内启动完整的路由，
+// 按用户的实际操作顺序调用HTTP接口。需要本机可用的Docker，运行方式：
+//
+//	make test-integration
+package integration
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"cloudpan/internal/api/routes"
+	"cloudpan/internal/pkg/cache"
+	"cloudpan/internal/pkg/config"
+	"cloudpan/internal/pkg/database"
+	"cloudpan/internal/pkg/logger"
+	"cloudpan/internal/pkg/utils"
+)
+
+// configPath 集成测试配置文件，依赖服务地址在启动容器后覆盖
+const configPath = "testdata/config.yaml"
+
+// server 全部测试共用的服务实例
+var server *httptest.Server
+
+func TestMain(m *testing.M) {
+	os.Exit(run(m))
+}
+
+// run 启动依赖服务和应用并执行测试，返回退出码；容器在返回前删除
+func run(m *testing.M) int {
+	svc, err := startServices()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "integration: %v\n", err)
+		return 1
+	}
+	defer svc.Close()
+
+	if err := setupApp(svc); err != nil {
+		fmt.Fprintf(os.Stderr, "integration: %v\n", err)
+		return 1
+	}
+	defer func() {
+		_ = database.Shutdown()
+	}()
+
+	gin.SetMode(gin.TestMode)
+	server = httptest.NewServer(routes.SetupRouter())
+	defer server.Close()
+
+	return m.Run()
+}
+
+// setupApp 按服务启动流程加载配置、连接数据库和Redis、执行迁移并创建全局存储
+func setupApp(svc *services) error {
+	if err := config.LoadFromFile(configPath); err != nil {
+		return fmt.Errorf("加载配置失败: %w", err)
+	}
+	cfg := config.AppConfig
+	cfg.Database.MySQL.Host, cfg.Database.MySQL.Port = svc.MySQL.Host, svc.MySQL.Port
+	cfg.Database.MySQL.Password = mysqlPassword
+	cfg.Database.MySQL.DBName = mysqlDatabase
+	cfg.Redis.Host, cfg.Redis.Port = svc.Redis.Host, svc.Redis.Port
+	cfg.Storage.S3.Endpoint = svc.MinIO.String()
+	cfg.Storage.S3.Bucket = minioBucket
+	cfg.Storage.S3.AccessKeyID, cfg.Storage.S3.SecretAccessKey = minioAccessKey, minioSecretKey
+
+	if err := logger.InitializeLoggerSystem(logger.InitConfig{
+		AppLog: logger.LogConfig{Level: cfg.Log.Level, Format: cfg.Log.Format, Output: cfg.Log.Output},
+	}); err != nil {
+		return fmt.Errorf("初始化日志失败: %w", err)
+	}
+	if err := database.Init(); err != nil {
+		return fmt.Errorf("连接数据库失败: %w", err)
+	}
+	if err := cache.InitRedis(); err != nil {
+		return fmt.Errorf("连接Redis失败: %w", err)
+	}
+
+	// 与 cmd/migrate -action migrate 相同的迁移
+	if err := database.MigrateAllModels(&database.MigrationConfig{AutoMigrate: true, CreateIndex: true}); err != nil {
+		return fmt.Errorf("执行数据库迁移失败: %w", err)
+	}
+
+	// 令牌吊销和会话保存在Redis中，与多实例部署相同
+	cache.SetDefaultTokenStore(cache.NewRedisTokenStore(cache.RedisClient, utils.DefaultRefreshExpiry))
+	cache.SetDefaultRefreshTokenStore(cache.NewRedisRefreshTokenStore(cache.RedisClient))
+	cache.SetDefaultSessionStore(cache.NewRedisSessionStore(cache.RedisClient))
+	return nil
+}